@@ -0,0 +1,407 @@
+package main
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"bitriver-live/internal/auth"
+	"bitriver-live/internal/auth/oauth"
+	"bitriver-live/internal/chat"
+	"bitriver-live/internal/ingest"
+	"bitriver-live/internal/server"
+	"bitriver-live/internal/storage"
+)
+
+// configCheckTimeout bounds each connectivity probe run by --check-config and
+// --strict-config, so an unreachable dependency fails fast instead of hanging
+// startup.
+const configCheckTimeout = 5 * time.Second
+
+// certificateExpiryWarning is how close to expiry a TLS certificate may get
+// before the configuration check warns about it.
+const certificateExpiryWarning = 14 * 24 * time.Hour
+
+type checkStatus string
+
+const (
+	checkOK   checkStatus = "ok"
+	checkWarn checkStatus = "warn"
+	checkFail checkStatus = "fail"
+)
+
+// configCheck is one line of the configuration report.
+type configCheck struct {
+	Component string
+	Status    checkStatus
+	Detail    string
+}
+
+// configReport collects configuration checks in the order they ran.
+type configReport struct {
+	Checks []configCheck
+}
+
+func (r *configReport) add(checks ...configCheck) {
+	r.Checks = append(r.Checks, checks...)
+}
+
+// fail records a configuration error found while resolving flags and
+// environment variables, before any dependency is probed.
+func (r *configReport) fail(component string, err error) {
+	r.add(configCheck{Component: component, Status: checkFail, Detail: err.Error()})
+}
+
+func (r configReport) count(status checkStatus) int {
+	n := 0
+	for _, check := range r.Checks {
+		if check.Status == status {
+			n++
+		}
+	}
+	return n
+}
+
+// Failed reports whether any check failed. Warnings do not fail the report.
+func (r configReport) Failed() bool {
+	return r.count(checkFail) > 0
+}
+
+// Write prints the report as an aligned COMPONENT/STATUS/DETAIL table
+// followed by a one-line summary.
+func (r configReport) Write(w io.Writer) error {
+	table := tabwriter.NewWriter(w, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(table, "COMPONENT\tSTATUS\tDETAIL")
+	for _, check := range r.Checks {
+		fmt.Fprintf(table, "%s\t%s\t%s\n", check.Component, check.Status, check.Detail)
+	}
+	if err := table.Flush(); err != nil {
+		return err
+	}
+	_, err := fmt.Fprintf(w, "%d ok, %d warn, %d fail\n", r.count(checkOK), r.count(checkWarn), r.count(checkFail))
+	return err
+}
+
+// logConfigReport logs each check at a level matching its status, for
+// --strict-config runs where the report is not printed.
+func logConfigReport(logger *slog.Logger, r configReport) {
+	for _, check := range r.Checks {
+		args := []any{"component", check.Component, "detail", check.Detail}
+		switch check.Status {
+		case checkFail:
+			logger.Error("configuration check failed", args...)
+		case checkWarn:
+			logger.Warn("configuration check warning", args...)
+		default:
+			logger.Debug("configuration check passed", args...)
+		}
+	}
+}
+
+// configCheckInput is the resolved configuration the checks validate.
+type configCheckInput struct {
+	OAuthProviders []oauth.ProviderConfig
+	StorageDriver  string
+	StorageDSN     string
+	DataPath       string
+	Session        sessionStoreConfig
+	TLS            server.TLSConfig
+	ObjectStorage  storage.ObjectStorageConfig
+	// IngestHealth probes the ingest services; nil when ingest is disabled.
+	IngestHealth func(context.Context) []ingest.HealthStatus
+	ChatDriver   string
+	ChatQueue    chat.RedisQueueConfig
+	RateLimit    server.RateLimitConfig
+}
+
+// configProbes reach the external dependencies. Tests replace them with
+// fakes; newConfigProbes returns the real clients.
+type configProbes struct {
+	Postgres      func(ctx context.Context, dsn string) error
+	SessionRedis  func(ctx context.Context, cfg auth.RedisSessionStoreConfig) error
+	ChatRedis     func(ctx context.Context, cfg chat.RedisQueueConfig) error
+	RateRedis     func(ctx context.Context, cfg server.RateLimitConfig) error
+	ObjectStorage func(ctx context.Context, cfg storage.ObjectStorageConfig) error
+}
+
+func newConfigProbes(timeout time.Duration) configProbes {
+	return configProbes{
+		Postgres: func(ctx context.Context, dsn string) error {
+			repo, err := storage.NewPostgresRepository(dsn, storage.WithPostgresAcquireTimeout(timeout))
+			if err != nil {
+				return err
+			}
+			if closer, ok := repo.(interface{ Close(context.Context) error }); ok {
+				defer func() {
+					_ = closer.Close(ctx)
+				}()
+			}
+			pinger, ok := repo.(interface{ Ping(context.Context) error })
+			if !ok {
+				return nil
+			}
+			return pinger.Ping(ctx)
+		},
+		SessionRedis: func(ctx context.Context, cfg auth.RedisSessionStoreConfig) error {
+			if cfg.Timeout <= 0 {
+				cfg.Timeout = timeout
+			}
+			store, err := auth.NewRedisSessionStore(cfg)
+			if err != nil {
+				return err
+			}
+			defer func() {
+				_ = store.Close(ctx)
+			}()
+			return store.Ping(ctx)
+		},
+		ChatRedis: func(ctx context.Context, cfg chat.RedisQueueConfig) error {
+			if cfg.DialTimeout <= 0 {
+				cfg.DialTimeout = timeout
+			}
+			return chat.PingRedisQueue(ctx, cfg)
+		},
+		RateRedis:     server.PingRateLimitRedis,
+		ObjectStorage: storage.CheckObjectStorage,
+	}
+}
+
+// runConfigChecks validates every configured dependency, giving each probe
+// its own timeout.
+func runConfigChecks(ctx context.Context, in configCheckInput, probes configProbes, timeout time.Duration, now time.Time) configReport {
+	probe := func(fn func(context.Context) error) error {
+		probeCtx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return fn(probeCtx)
+	}
+
+	var report configReport
+	report.add(checkOAuth(in.OAuthProviders))
+	report.add(checkDatastore(in.StorageDriver, in.StorageDSN, in.DataPath, func(dsn string) error {
+		return probe(func(ctx context.Context) error { return probes.Postgres(ctx, dsn) })
+	}))
+	report.add(checkSessionStore(in.Session, in.StorageDSN, func(dsn string) error {
+		return probe(func(ctx context.Context) error { return probes.Postgres(ctx, dsn) })
+	}, func(cfg auth.RedisSessionStoreConfig) error {
+		return probe(func(ctx context.Context) error { return probes.SessionRedis(ctx, cfg) })
+	}))
+	report.add(checkTLS(in.TLS, now)...)
+	report.add(checkObjectStorage(in.ObjectStorage, func(cfg storage.ObjectStorageConfig) error {
+		return probe(func(ctx context.Context) error { return probes.ObjectStorage(ctx, cfg) })
+	}))
+	if in.IngestHealth == nil {
+		report.add(checkIngest(nil)...)
+	} else {
+		probeCtx, cancel := context.WithTimeout(ctx, timeout)
+		report.add(checkIngest(in.IngestHealth(probeCtx))...)
+		cancel()
+	}
+	if strings.EqualFold(in.ChatDriver, "redis") {
+		report.add(checkRedis("chat-queue", func() error {
+			return probe(func(ctx context.Context) error { return probes.ChatRedis(ctx, in.ChatQueue) })
+		}))
+	}
+	if determineRateLimiterDriver(in.RateLimit) == "redis" {
+		report.add(checkRedis("rate-limit-redis", func() error {
+			return probe(func(ctx context.Context) error { return probes.RateRedis(ctx, in.RateLimit) })
+		}))
+	}
+	return report
+}
+
+func checkOAuth(providers []oauth.ProviderConfig) configCheck {
+	check := configCheck{Component: "oauth", Status: checkOK}
+	if len(providers) == 0 {
+		check.Detail = "no providers configured"
+		return check
+	}
+	names := make([]string, 0, len(providers))
+	for _, provider := range providers {
+		names = append(names, provider.Name)
+	}
+	check.Detail = fmt.Sprintf("%d providers (%s)", len(providers), strings.Join(names, ", "))
+	return check
+}
+
+func checkDatastore(driver, dsn, dataPath string, ping func(dsn string) error) configCheck {
+	check := configCheck{Component: "datastore"}
+	switch driver {
+	case "postgres":
+		if err := ping(dsn); err != nil {
+			check.Status = checkFail
+			check.Detail = fmt.Sprintf("postgres %s: %v", redactDSN(dsn), err)
+			return check
+		}
+		check.Status = checkOK
+		check.Detail = "postgres " + redactDSN(dsn)
+	case "json":
+		dir := filepath.Dir(dataPath)
+		if info, err := os.Stat(dir); err != nil || !info.IsDir() {
+			check.Status = checkFail
+			check.Detail = fmt.Sprintf("json datastore directory %s does not exist", dir)
+			return check
+		}
+		if _, err := os.Stat(dataPath); errors.Is(err, os.ErrNotExist) {
+			check.Status = checkWarn
+			check.Detail = fmt.Sprintf("json datastore %s does not exist yet and will be created", dataPath)
+			return check
+		}
+		check.Status = checkOK
+		check.Detail = "json " + dataPath
+	default:
+		check.Status = checkFail
+		check.Detail = fmt.Sprintf("unsupported storage driver %q", driver)
+	}
+	return check
+}
+
+func checkSessionStore(cfg sessionStoreConfig, storageDSN string, pingPostgres func(dsn string) error, pingRedis func(auth.RedisSessionStoreConfig) error) configCheck {
+	check := configCheck{Component: "session-store"}
+	switch cfg.Driver {
+	case "memory":
+		check.Status = checkWarn
+		check.Detail = "memory store; sessions are lost on restart and not shared between instances"
+	case "postgres":
+		if cfg.DSN == storageDSN {
+			check.Status = checkOK
+			check.Detail = "postgres, shared with the datastore"
+			return check
+		}
+		if err := pingPostgres(cfg.DSN); err != nil {
+			check.Status = checkFail
+			check.Detail = fmt.Sprintf("postgres %s: %v", redactDSN(cfg.DSN), err)
+			return check
+		}
+		check.Status = checkOK
+		check.Detail = "postgres " + redactDSN(cfg.DSN)
+	case "redis":
+		if err := pingRedis(cfg.Redis); err != nil {
+			check.Status = checkFail
+			check.Detail = "redis: " + err.Error()
+			return check
+		}
+		check.Status = checkOK
+		check.Detail = "redis"
+	default:
+		check.Status = checkFail
+		check.Detail = fmt.Sprintf("unsupported session store driver %q", cfg.Driver)
+	}
+	return check
+}
+
+// checkTLS loads the certificate pair and client CA bundle the way the
+// server will, warning when the certificate expires soon.
+func checkTLS(cfg server.TLSConfig, now time.Time) []configCheck {
+	certFile := strings.TrimSpace(cfg.CertFile)
+	keyFile := strings.TrimSpace(cfg.KeyFile)
+	clientCAFile := strings.TrimSpace(cfg.ClientCAFile)
+	if certFile == "" && keyFile == "" {
+		if clientCAFile != "" {
+			return []configCheck{{Component: "tls", Status: checkFail, Detail: "client CA requires a certificate and key"}}
+		}
+		return []configCheck{{Component: "tls", Status: checkOK, Detail: "disabled; serving plain HTTP"}}
+	}
+	if certFile == "" || keyFile == "" {
+		return []configCheck{{Component: "tls", Status: checkFail, Detail: "both a certificate and a key are required"}}
+	}
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return []configCheck{{Component: "tls", Status: checkFail, Detail: err.Error()}}
+	}
+	check := configCheck{Component: "tls", Status: checkOK, Detail: certFile}
+	if leaf, err := x509.ParseCertificate(pair.Certificate[0]); err == nil {
+		switch {
+		case now.After(leaf.NotAfter):
+			check.Status = checkFail
+			check.Detail = fmt.Sprintf("certificate %s expired at %s", certFile, leaf.NotAfter.UTC().Format(time.RFC3339))
+		case leaf.NotAfter.Sub(now) < certificateExpiryWarning:
+			check.Status = checkWarn
+			check.Detail = fmt.Sprintf("certificate %s expires at %s", certFile, leaf.NotAfter.UTC().Format(time.RFC3339))
+		default:
+			check.Detail = fmt.Sprintf("%s valid until %s", certFile, leaf.NotAfter.UTC().Format(time.RFC3339))
+		}
+	}
+	checks := []configCheck{check}
+	if clientCAFile != "" {
+		caCheck := configCheck{Component: "tls-client-ca", Status: checkOK, Detail: clientCAFile}
+		pemData, err := os.ReadFile(clientCAFile)
+		if err != nil {
+			caCheck.Status = checkFail
+			caCheck.Detail = err.Error()
+		} else if !x509.NewCertPool().AppendCertsFromPEM(pemData) {
+			caCheck.Status = checkFail
+			caCheck.Detail = fmt.Sprintf("no certificates found in %s", clientCAFile)
+		}
+		checks = append(checks, caCheck)
+	}
+	return checks
+}
+
+func checkObjectStorage(cfg storage.ObjectStorageConfig, head func(storage.ObjectStorageConfig) error) configCheck {
+	check := configCheck{Component: "object-storage"}
+	endpoint := strings.TrimSpace(cfg.Endpoint)
+	bucket := strings.TrimSpace(cfg.Bucket)
+	if endpoint == "" && bucket == "" {
+		check.Status = checkOK
+		check.Detail = "disabled; recordings stay on local disk"
+		return check
+	}
+	if endpoint == "" || bucket == "" {
+		check.Status = checkFail
+		check.Detail = "both an endpoint and a bucket are required"
+		return check
+	}
+	if err := head(cfg); err != nil {
+		check.Status = checkFail
+		check.Detail = err.Error()
+		return check
+	}
+	check.Status = checkOK
+	check.Detail = fmt.Sprintf("bucket %s at %s", bucket, endpoint)
+	if strings.TrimSpace(cfg.AccessKey) == "" || strings.TrimSpace(cfg.SecretKey) == "" {
+		check.Status = checkWarn
+		check.Detail += " reachable without credentials; uploads may be rejected"
+	}
+	return check
+}
+
+// checkIngest maps the ingest controller's health probes onto report lines.
+// A nil slice means ingest is not configured.
+func checkIngest(statuses []ingest.HealthStatus) []configCheck {
+	if statuses == nil {
+		return []configCheck{{Component: "ingest", Status: checkWarn, Detail: "not configured; channels cannot go live"}}
+	}
+	checks := make([]configCheck, 0, len(statuses))
+	for _, status := range statuses {
+		check := configCheck{Component: "ingest-" + status.Component, Detail: status.Detail}
+		switch status.Status {
+		case "ok":
+			check.Status = checkOK
+		case "unknown", "disabled":
+			check.Status = checkWarn
+		default:
+			check.Status = checkFail
+		}
+		if check.Detail == "" {
+			check.Detail = status.Status
+		}
+		checks = append(checks, check)
+	}
+	return checks
+}
+
+func checkRedis(component string, ping func() error) configCheck {
+	if err := ping(); err != nil {
+		return configCheck{Component: component, Status: checkFail, Detail: "redis: " + err.Error()}
+	}
+	return configCheck{Component: component, Status: checkOK, Detail: "redis"}
+}
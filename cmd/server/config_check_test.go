@@ -0,0 +1,297 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/auth"
+	"bitriver-live/internal/auth/oauth"
+	"bitriver-live/internal/chat"
+	"bitriver-live/internal/ingest"
+	"bitriver-live/internal/server"
+	"bitriver-live/internal/storage"
+)
+
+// writeTestCertificate writes a self-signed certificate valid until notAfter
+// and its key into dir, returning their paths.
+func writeTestCertificate(t *testing.T, dir string, notAfter time.Time) (string, string) {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "bitriver.test"},
+		NotBefore:             notAfter.Add(-365 * 24 * time.Hour),
+		NotAfter:              notAfter,
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	certPath := filepath.Join(dir, "cert.pem")
+	keyPath := filepath.Join(dir, "key.pem")
+	if err := os.WriteFile(certPath, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600); err != nil {
+		t.Fatalf("write certificate: %v", err)
+	}
+	if err := os.WriteFile(keyPath, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600); err != nil {
+		t.Fatalf("write key: %v", err)
+	}
+	return certPath, keyPath
+}
+
+func TestCheckOAuth(t *testing.T) {
+	if check := checkOAuth(nil); check.Status != checkOK || check.Detail != "no providers configured" {
+		t.Fatalf("unexpected check without providers: %+v", check)
+	}
+	check := checkOAuth([]oauth.ProviderConfig{{Name: "github"}, {Name: "google"}})
+	if check.Status != checkOK || check.Detail != "2 providers (github, google)" {
+		t.Fatalf("unexpected check with providers: %+v", check)
+	}
+}
+
+func TestCheckDatastore(t *testing.T) {
+	dir := t.TempDir()
+	existing := filepath.Join(dir, "store.json")
+	if err := os.WriteFile(existing, []byte("{}"), 0o600); err != nil {
+		t.Fatalf("write datastore: %v", err)
+	}
+	unreachable := func(string) error { return errors.New("connection refused") }
+	reachable := func(string) error { return nil }
+
+	cases := []struct {
+		name   string
+		driver string
+		dsn    string
+		path   string
+		ping   func(string) error
+		want   checkStatus
+	}{
+		{name: "postgres reachable", driver: "postgres", dsn: "postgres://user:secret@db/bitriver", ping: reachable, want: checkOK},
+		{name: "postgres unreachable", driver: "postgres", dsn: "postgres://user:secret@db/bitriver", ping: unreachable, want: checkFail},
+		{name: "json existing", driver: "json", path: existing, want: checkOK},
+		{name: "json created on start", driver: "json", path: filepath.Join(dir, "new.json"), want: checkWarn},
+		{name: "json missing directory", driver: "json", path: filepath.Join(dir, "missing", "store.json"), want: checkFail},
+		{name: "unsupported driver", driver: "sqlite", want: checkFail},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			check := checkDatastore(tc.driver, tc.dsn, tc.path, tc.ping)
+			if check.Status != tc.want {
+				t.Fatalf("expected %s, got %+v", tc.want, check)
+			}
+			if strings.Contains(check.Detail, "secret") {
+				t.Fatalf("expected the DSN password to be redacted, got %q", check.Detail)
+			}
+		})
+	}
+}
+
+func TestCheckSessionStore(t *testing.T) {
+	failPostgres := func(string) error { return errors.New("timeout") }
+	failRedis := func(auth.RedisSessionStoreConfig) error { return errors.New("no route to host") }
+	passRedis := func(auth.RedisSessionStoreConfig) error { return nil }
+
+	if check := checkSessionStore(sessionStoreConfig{Driver: "memory"}, "", failPostgres, failRedis); check.Status != checkWarn {
+		t.Fatalf("expected memory sessions to warn, got %+v", check)
+	}
+	shared := sessionStoreConfig{Driver: "postgres", DSN: "postgres://db/bitriver"}
+	if check := checkSessionStore(shared, shared.DSN, failPostgres, failRedis); check.Status != checkOK {
+		t.Fatalf("expected the shared datastore DSN not to be probed twice, got %+v", check)
+	}
+	separate := sessionStoreConfig{Driver: "postgres", DSN: "postgres://sessions/bitriver"}
+	if check := checkSessionStore(separate, shared.DSN, failPostgres, failRedis); check.Status != checkFail || !strings.Contains(check.Detail, "timeout") {
+		t.Fatalf("expected an unreachable session database to fail, got %+v", check)
+	}
+	if check := checkSessionStore(sessionStoreConfig{Driver: "redis"}, "", failPostgres, passRedis); check.Status != checkOK {
+		t.Fatalf("expected reachable redis to pass, got %+v", check)
+	}
+	if check := checkSessionStore(sessionStoreConfig{Driver: "redis"}, "", failPostgres, failRedis); check.Status != checkFail {
+		t.Fatalf("expected unreachable redis to fail, got %+v", check)
+	}
+}
+
+func TestCheckTLS(t *testing.T) {
+	now := time.Date(2026, 1, 1, 0, 0, 0, 0, time.UTC)
+
+	if checks := checkTLS(server.TLSConfig{}, now); len(checks) != 1 || checks[0].Status != checkOK {
+		t.Fatalf("expected disabled TLS to pass, got %+v", checks)
+	}
+	if checks := checkTLS(server.TLSConfig{CertFile: "cert.pem"}, now); checks[0].Status != checkFail {
+		t.Fatalf("expected a certificate without a key to fail, got %+v", checks)
+	}
+
+	validCert, validKey := writeTestCertificate(t, t.TempDir(), now.Add(365*24*time.Hour))
+	checks := checkTLS(server.TLSConfig{CertFile: validCert, KeyFile: validKey, ClientCAFile: validCert}, now)
+	if len(checks) != 2 || checks[0].Status != checkOK || checks[1].Status != checkOK {
+		t.Fatalf("expected a valid pair and client CA to pass, got %+v", checks)
+	}
+
+	expiringCert, expiringKey := writeTestCertificate(t, t.TempDir(), now.Add(72*time.Hour))
+	if checks := checkTLS(server.TLSConfig{CertFile: expiringCert, KeyFile: expiringKey}, now); checks[0].Status != checkWarn {
+		t.Fatalf("expected a certificate expiring soon to warn, got %+v", checks)
+	}
+
+	expiredCert, expiredKey := writeTestCertificate(t, t.TempDir(), now.Add(-time.Hour))
+	if checks := checkTLS(server.TLSConfig{CertFile: expiredCert, KeyFile: expiredKey}, now); checks[0].Status != checkFail {
+		t.Fatalf("expected an expired certificate to fail, got %+v", checks)
+	}
+
+	if checks := checkTLS(server.TLSConfig{CertFile: validCert, KeyFile: expiredKey}, now); checks[0].Status != checkFail {
+		t.Fatalf("expected a mismatched key to fail, got %+v", checks)
+	}
+
+	bogusCA := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(bogusCA, []byte("not a certificate"), 0o600); err != nil {
+		t.Fatalf("write CA: %v", err)
+	}
+	checks = checkTLS(server.TLSConfig{CertFile: validCert, KeyFile: validKey, ClientCAFile: bogusCA}, now)
+	if len(checks) != 2 || checks[1].Status != checkFail {
+		t.Fatalf("expected an empty client CA bundle to fail, got %+v", checks)
+	}
+}
+
+func TestCheckObjectStorage(t *testing.T) {
+	cfg := storage.ObjectStorageConfig{Endpoint: "http://minio:9000", Bucket: "vods", AccessKey: "key", SecretKey: "secret"}
+	pass := func(storage.ObjectStorageConfig) error { return nil }
+	fail := func(storage.ObjectStorageConfig) error { return errors.New("head bucket vods: 404") }
+
+	if check := checkObjectStorage(storage.ObjectStorageConfig{}, fail); check.Status != checkOK {
+		t.Fatalf("expected unconfigured object storage to pass, got %+v", check)
+	}
+	if check := checkObjectStorage(storage.ObjectStorageConfig{Endpoint: cfg.Endpoint}, pass); check.Status != checkFail {
+		t.Fatalf("expected an endpoint without a bucket to fail, got %+v", check)
+	}
+	if check := checkObjectStorage(cfg, pass); check.Status != checkOK {
+		t.Fatalf("expected a reachable bucket to pass, got %+v", check)
+	}
+	if check := checkObjectStorage(cfg, fail); check.Status != checkFail || !strings.Contains(check.Detail, "404") {
+		t.Fatalf("expected a missing bucket to fail, got %+v", check)
+	}
+	anonymous := storage.ObjectStorageConfig{Endpoint: cfg.Endpoint, Bucket: cfg.Bucket}
+	if check := checkObjectStorage(anonymous, pass); check.Status != checkWarn {
+		t.Fatalf("expected a bucket without credentials to warn, got %+v", check)
+	}
+}
+
+func TestCheckIngest(t *testing.T) {
+	if checks := checkIngest(nil); len(checks) != 1 || checks[0].Status != checkWarn {
+		t.Fatalf("expected missing ingest to warn, got %+v", checks)
+	}
+	checks := checkIngest([]ingest.HealthStatus{
+		{Component: "srs", Status: "ok"},
+		{Component: "ovenmediaengine", Status: "unknown", Detail: "base URL not configured"},
+		{Component: "transcoder", Status: "error", Detail: "connection refused"},
+	})
+	want := []configCheck{
+		{Component: "ingest-srs", Status: checkOK, Detail: "ok"},
+		{Component: "ingest-ovenmediaengine", Status: checkWarn, Detail: "base URL not configured"},
+		{Component: "ingest-transcoder", Status: checkFail, Detail: "connection refused"},
+	}
+	if len(checks) != len(want) {
+		t.Fatalf("expected %d checks, got %+v", len(want), checks)
+	}
+	for i := range want {
+		if checks[i] != want[i] {
+			t.Fatalf("check %d: expected %+v, got %+v", i, want[i], checks[i])
+		}
+	}
+}
+
+func TestRunConfigChecksProbesConfiguredRedis(t *testing.T) {
+	var chatProbed, rateProbed bool
+	probes := configProbes{
+		Postgres:     func(context.Context, string) error { return nil },
+		SessionRedis: func(context.Context, auth.RedisSessionStoreConfig) error { return nil },
+		ChatRedis: func(ctx context.Context, cfg chat.RedisQueueConfig) error {
+			chatProbed = true
+			if _, ok := ctx.Deadline(); !ok {
+				t.Fatal("expected the probe context to carry a deadline")
+			}
+			return nil
+		},
+		RateRedis: func(context.Context, server.RateLimitConfig) error {
+			rateProbed = true
+			return errors.New("dial tcp: connection refused")
+		},
+		ObjectStorage: func(context.Context, storage.ObjectStorageConfig) error { return nil },
+	}
+	in := configCheckInput{
+		StorageDriver: "postgres",
+		StorageDSN:    "postgres://db/bitriver",
+		Session:       sessionStoreConfig{Driver: "postgres", DSN: "postgres://db/bitriver"},
+		IngestHealth: func(context.Context) []ingest.HealthStatus {
+			return []ingest.HealthStatus{{Component: "srs", Status: "ok"}}
+		},
+		ChatDriver: "redis",
+		RateLimit:  server.RateLimitConfig{RedisAddr: "redis:6379"},
+	}
+	report := runConfigChecks(context.Background(), in, probes, time.Second, time.Now())
+	if !chatProbed || !rateProbed {
+		t.Fatalf("expected both redis probes to run, chat=%v rate=%v", chatProbed, rateProbed)
+	}
+	if !report.Failed() {
+		t.Fatalf("expected the unreachable rate limit redis to fail the report: %+v", report.Checks)
+	}
+	last := report.Checks[len(report.Checks)-1]
+	if last.Component != "rate-limit-redis" || last.Status != checkFail {
+		t.Fatalf("unexpected final check %+v", last)
+	}
+
+	chatProbed = false
+	in.ChatDriver = "memory"
+	in.RateLimit = server.RateLimitConfig{}
+	report = runConfigChecks(context.Background(), in, probes, time.Second, time.Now())
+	if chatProbed || report.Failed() {
+		t.Fatalf("expected in-memory queues to skip redis probes, got %+v", report.Checks)
+	}
+}
+
+func TestConfigReportWrite(t *testing.T) {
+	var report configReport
+	report.add(
+		configCheck{Component: "oauth", Status: checkOK, Detail: "no providers configured"},
+		configCheck{Component: "session-store", Status: checkWarn, Detail: "memory store"},
+	)
+	report.fail("datastore", errors.New("connection refused"))
+
+	var buf bytes.Buffer
+	if err := report.Write(&buf); err != nil {
+		t.Fatalf("Write: %v", err)
+	}
+	want := strings.Join([]string{
+		"COMPONENT      STATUS  DETAIL",
+		"oauth          ok      no providers configured",
+		"session-store  warn    memory store",
+		"datastore      fail    connection refused",
+		"1 ok, 1 warn, 1 fail",
+		"",
+	}, "\n")
+	if buf.String() != want {
+		t.Fatalf("unexpected report:\n%s\nwant:\n%s", buf.String(), want)
+	}
+	if !report.Failed() {
+		t.Fatal("expected a report with a failure to fail")
+	}
+}
@@ -18,12 +18,19 @@ import (
 	"strings"
 	"syscall"
 	"time"
+	// Schedule time zones must resolve on images without zoneinfo.
+	_ "time/tzdata"
 
 	"bitriver-live/internal/api"
 	"bitriver-live/internal/auth"
 	"bitriver-live/internal/auth/oauth"
 	"bitriver-live/internal/chat"
+	"bitriver-live/internal/events"
 	"bitriver-live/internal/ingest"
+	"bitriver-live/internal/jobs"
+	"bitriver-live/internal/mail"
+	"bitriver-live/internal/malware"
+	"bitriver-live/internal/observability/health"
 	"bitriver-live/internal/observability/logging"
 	"bitriver-live/internal/observability/metrics"
 	"bitriver-live/internal/server"
@@ -67,12 +74,33 @@ func (kv *keyValueFlag) Set(value string) error {
 func main() {
 	addr := flag.String("addr", "", "HTTP listen address")
 	mode := flag.String("mode", "", "server runtime mode (development or production)")
+	checkConfig := flag.Bool("check-config", false, "validate configuration and probe dependencies, print a report, and exit without serving")
+	strictConfig := flag.Bool("strict-config", false, "run the --check-config validation at startup and refuse to start when any check fails")
 	allowSelfSignup := flag.Bool("allow-self-signup", false, "allow unauthenticated viewers to register accounts")
+	maintenanceMode := flag.Bool("maintenance-mode", false, "start in read-only maintenance mode, rejecting API writes")
+	maintenanceReason := flag.String("maintenance-reason", "", "reason shown to users while maintenance mode is active")
+	legacyAPISunset := flag.String("legacy-api-sunset", "", "date (YYYY-MM-DD or RFC 3339) advertised in the Sunset header of deprecated responses under the unversioned /api/ prefix")
+	readCacheDisabled := flag.Bool("read-cache-disabled", false, "disable the read cache in front of directory and public channel endpoints")
+	readCacheTTL := flag.Duration("read-cache-ttl", 0, "how long directory and public channel payloads are cached (default 5s)")
+	componentStaleAfter := flag.Duration("component-stale-after", 0, "how long a background worker may go without a heartbeat before it is reported as stalled (default 2m)")
+	jobWorkers := flag.Int("job-workers", 0, "number of background job queue workers (default 2)")
+	startingTimeout := flag.Duration("starting-timeout", 0, "how long a stream may stay starting before it counts as stuck and can be recovered (default 2m)")
+	recordingPlaybackTTL := flag.Duration("recording-playback-ttl", 0, "how long presigned recording playback URLs stay valid (default 15m)")
+	keyLeakSourceLimit := flag.Int("key-leak-source-limit", 0, "number of distinct networks a stream key may be published from within --key-leak-window before the owner is warned (default 3)")
+	keyLeakWindow := flag.Duration("key-leak-window", 0, "window over which distinct stream key publish networks are counted (default 24h)")
+	sessionPeakInterval := flag.Duration("session-peak-interval", 0, "minimum gap between writes of a live session's viewer peak (default 30s)")
+	streamRequestBudget := flag.Duration("stream-request-budget", 0, "how long stream start and stop requests wait on ingest before answering 504 (default 25s)")
+	ingestHealthInterval := flag.Duration("ingest-health-interval", 0, "how often ingest services are probed for /healthz (default 30s)")
+	ingestHealthMaxBackoff := flag.Duration("ingest-health-max-backoff", 0, "longest delay between probes of an ingest service that keeps failing (default 5m)")
+	recordingRetentionInterval := flag.Duration("recording-retention-interval", 0, "how often the job queue purges recordings past their retention window (default 1h)")
+	statsRollupInterval := flag.Duration("stats-rollup-interval", 0, "how often the job queue recomputes the platform statistics served by /api/stats (default 15m)")
+	readyRequiresComponents := flag.Bool("ready-requires-components", false, "fail /readyz when a background worker is degraded or stalled")
 	sessionCookieCrossSite := flag.Bool("session-cookie-cross-site", false, "emit SameSite=None; Secure session cookies for cross-site viewer deployments")
 	adminCORSOrigins := flag.String("admin-cors-origins", "", "comma separated origins allowed to access the control centre APIs")
 	viewerCORSOrigins := flag.String("viewer-cors-origins", "", "comma separated origins allowed to access viewer APIs")
 	securityCSP := flag.String("security-csp", "", "override the Content-Security-Policy header (empty uses the secure default)")
 	securityFrameAncestors := flag.String("security-frame-ancestors", "", "frame-ancestors directive used in the default Content-Security-Policy")
+	securityEmbedFrameAncestors := flag.String("security-embed-frame-ancestors", "", "frame-ancestors directive for the /embed/ player (default *)")
 	securityFrameOptions := flag.String("security-frame-options", "", "X-Frame-Options header value")
 	securityReferrerPolicy := flag.String("security-referrer-policy", "", "Referrer-Policy header value")
 	securityPermissionsPolicy := flag.String("security-permissions-policy", "", "Permissions-Policy header value")
@@ -82,26 +110,53 @@ func main() {
 	dataPath := flag.String("data", "", "path to JSON datastore")
 	storageDriver := flag.String("storage-driver", "", "datastore driver (json or postgres)")
 	postgresDSN := flag.String("postgres-dsn", "", "Postgres connection string")
+	postgresReplicaDSNs := flag.String("postgres-replica-dsns", "", "comma-separated Postgres read replica connection strings for lag-tolerant reads")
 	postgresMaxConns := flag.Int("postgres-max-conns", 0, "maximum connections in the Postgres pool")
 	postgresMinConns := flag.Int("postgres-min-conns", 0, "minimum idle connections maintained by the Postgres pool")
 	postgresMaxConnLifetime := flag.Duration("postgres-max-conn-lifetime", 0, "maximum lifetime for a pooled Postgres connection")
 	postgresMaxConnIdle := flag.Duration("postgres-max-conn-idle", 0, "maximum idle time for a pooled Postgres connection")
 	postgresHealthInterval := flag.Duration("postgres-health-interval", 0, "interval between Postgres health checks")
 	postgresAcquireTimeout := flag.Duration("postgres-acquire-timeout", 0, "timeout when acquiring a Postgres connection from the pool")
+	postgresStatementTimeout := flag.Duration("postgres-statement-timeout", 0, "statement_timeout applied inside Postgres repository transactions")
+	postgresTxRetries := flag.Int("postgres-tx-retries", -1, "retries for Postgres transactions aborted by serialization failures or deadlocks (0 disables)")
 	postgresAppName := flag.String("postgres-app-name", "", "application_name reported to Postgres")
 
 	// Session flags (env: BITRIVER_LIVE_SESSION_STORE, BITRIVER_LIVE_SESSION_POSTGRES_DSN, BITRIVER_LIVE_SESSION_TTL, BITRIVER_LIVE_SESSION_IDLE_TIMEOUT, BITRIVER_LIVE_SESSION_COOKIE_CROSS_SITE, BITRIVER_LIVE_ALLOW_SELF_SIGNUP).
-	sessionStoreDriver := flag.String("session-store", "", "session store driver (memory or postgres)")
+	sessionStoreDriver := flag.String("session-store", "", "session store driver (memory, postgres, or redis)")
 	sessionPostgresDSN := flag.String("session-postgres-dsn", "", "Postgres DSN for the session store")
+	// Session Redis flags (env: BITRIVER_LIVE_SESSION_REDIS_*).
+	sessionRedisAddr := flag.String("session-redis-addr", "", "Redis address for the session store")
+	sessionRedisAddrs := flag.String("session-redis-addrs", "", "comma separated Redis addresses for the session store")
+	sessionRedisUsername := flag.String("session-redis-username", "", "Redis username for the session store")
+	sessionRedisPassword := flag.String("session-redis-password", "", "Redis password for the session store")
+	sessionRedisMasterName := flag.String("session-redis-sentinel-master", "", "Redis sentinel master name for the session store")
+	sessionRedisPoolSize := flag.Int("session-redis-pool-size", 0, "maximum Redis connections for the session store")
+	sessionRedisTimeout := flag.Duration("session-redis-timeout", 0, "timeout for session store Redis operations (default 2s)")
+	sessionRedisTLSCA := flag.String("session-redis-tls-ca", "", "path to Redis TLS CA certificate for the session store")
+	sessionRedisTLSCert := flag.String("session-redis-tls-cert", "", "path to Redis TLS client certificate for the session store")
+	sessionRedisTLSKey := flag.String("session-redis-tls-key", "", "path to Redis TLS client key for the session store")
+	sessionRedisTLSServerName := flag.String("session-redis-tls-server-name", "", "override Redis TLS server name for the session store")
+	sessionRedisTLSSkipVerify := flag.Bool("session-redis-tls-skip-verify", false, "skip Redis TLS verification for the session store")
 	sessionTTL := flag.Duration("session-ttl", 0, "absolute session lifetime (e.g. 168h)")
 	sessionIdleTimeout := flag.Duration("session-idle-timeout", 0, "idle timeout that refreshes session expiry on activity")
 
-	// TLS flags (env: BITRIVER_LIVE_TLS_CERT, BITRIVER_LIVE_TLS_KEY).
+	// Signed playback flags (env: BITRIVER_LIVE_PLAYBACK_*).
+	playbackSigningKey := flag.String("playback-signing-key", "", "HMAC key that signs playback URLs for restricted channels")
+	playbackSigningKeyPrevious := flag.String("playback-signing-key-previous", "", "comma separated retired playback keys still accepted during rotation")
+	playbackTokenTTL := flag.Duration("playback-token-ttl", 0, "lifetime of signed playback URLs (default 10m)")
+
+	// TLS flags (env: BITRIVER_LIVE_TLS_CERT, BITRIVER_LIVE_TLS_KEY,
+	// BITRIVER_LIVE_TLS_RELOAD_INTERVAL, BITRIVER_LIVE_TLS_CLIENT_CA).
 	tlsCert := flag.String("tls-cert", "", "path to TLS certificate file")
 	tlsKey := flag.String("tls-key", "", "path to TLS private key file")
+	tlsReloadInterval := flag.Duration("tls-reload-interval", 0, "how often the TLS certificate and key are checked for changes (default 1m)")
+	tlsClientCA := flag.String("tls-client-ca", "", "path to a PEM bundle of CAs that sign client certificates required for /api/admin/*")
 	logLevel := flag.String("log-level", "info", "log level (debug, info, warn, error)")
 	metricsToken := flag.String("metrics-token", "", "token required to scrape /metrics (Authorization bearer or X-Metrics-Token)")
 	metricsAllowNetworks := flag.String("metrics-allow-networks", "", "comma separated CIDR blocks or IPs allowed to scrape /metrics")
+	provisioningToken := flag.String("provisioning-token", "", "bearer token for the identity-provider user provisioning API (disabled when empty)")
+	ingestEventsToken := flag.String("ingest-events-token", "", "token the ingest server's media event callbacks must carry (disabled when empty)")
+	ingestIdleTimeout := flag.Duration("ingest-idle-timeout", 0, "how long a stream may stay interrupted after its media stops before it is stopped (default 2m)")
 
 	// Rate limiting flags (env: BITRIVER_LIVE_RATE_*).
 	globalRPS := flag.Float64("rate-global-rps", 0, "global request rate limit in requests per second")
@@ -138,6 +193,11 @@ func main() {
 	chatRedisTLSKey := flag.String("chat-queue-redis-tls-key", "", "path to Redis TLS client key for chat queue")
 	chatRedisTLSServerName := flag.String("chat-queue-redis-tls-server-name", "", "override Redis TLS server name for chat queue")
 	chatRedisTLSSkipVerify := flag.Bool("chat-queue-redis-tls-skip-verify", false, "skip Redis TLS verification for chat queue")
+	chatQueueMaxDeliveries := flag.Int("chat-queue-max-deliveries", 0, "deliveries before a failing chat event is dead-lettered (default 5)")
+	chatQueueRetryDelay := flag.Duration("chat-queue-retry-delay", 0, "delay before a failed chat event is redelivered; Redis reclaims entries idle this long (default 30s)")
+	chatRedisDeadLetterStream := flag.String("chat-queue-redis-dead-letter-stream", "", "Redis stream key for dead-lettered chat events (default <stream>:dead)")
+	chatBatchSize := flag.Int("chat-batch-size", 0, "most chat events the chat worker writes to storage at once (default 100)")
+	chatBatchWait := flag.Duration("chat-batch-wait", 0, "how long the chat worker waits for a batch of chat events to fill (default 25ms)")
 	viewerOrigin := flag.String("viewer-origin", "", "URL of the Next.js viewer runtime to proxy (e.g. http://127.0.0.1:3000)")
 	objectEndpoint := flag.String("object-endpoint", "", "object storage endpoint (e.g. http://127.0.0.1:9000)")
 	objectRegion := flag.String("object-region", "", "object storage region")
@@ -147,9 +207,29 @@ func main() {
 	objectUseSSL := flag.Bool("object-use-ssl", false, "enable TLS for object storage requests")
 	objectPrefix := flag.String("object-prefix", "", "object storage key prefix for recordings")
 	objectPublicEndpoint := flag.String("object-public-endpoint", "", "public endpoint used for playback URLs")
+	objectRestrictProfileImages := flag.Bool("object-restrict-profile-images", false, "require profile avatar and banner URLs to use the object storage public endpoint")
 	objectLifecycleDays := flag.Int("object-lifecycle-days", 0, "lifecycle policy in days for archived objects")
+	objectRequestTimeout := flag.Duration("object-request-timeout", 0, "per-attempt timeout for object storage deletes and small metadata writes")
+	objectUploadTimeout := flag.Duration("object-upload-timeout", 0, "per-attempt timeout for large object uploads and multipart parts")
+	objectMultipartThresholdMB := flag.Int("object-multipart-threshold-mb", 0, "payload size in MiB above which object uploads use multipart")
+	objectMaxRetries := flag.Int("object-max-retries", 0, "maximum retries for transient object storage failures")
 	recordingRetentionPublished := flag.String("recording-retention-published", "", "retention duration for published recordings (e.g. 720h, 0 disables expiry)")
 	recordingRetentionUnpublished := flag.String("recording-retention-unpublished", "", "retention duration for unpublished recordings")
+	channelStorageQuotaMB := flag.Int("channel-storage-quota-mb", 0, "default VOD storage quota per channel in MiB; admins can override it per channel (0 is unlimited)")
+	maxConcurrentStreams := flag.Int("max-concurrent-streams", 0, "default number of channels one account may stream on at once; admins can override it per user (0 is unlimited)")
+	secretKey := flag.String("secret-key", "", "base64 or hex 32-byte key that encrypts stored secrets such as restream keys and signs anonymous viewer cookies")
+	encryptionKeys := flag.String("encryption-keys", "", "comma separated id:key encryption keys for donation addresses, OAuth emails, and restream keys; the first seals new values")
+	smtpHost := flag.String("smtp-host", "", "SMTP relay host for outgoing email; empty logs emails instead of sending them")
+	smtpPort := flag.Int("smtp-port", 0, "SMTP relay port (defaults to 587 for starttls, 465 for tls, 25 for none)")
+	smtpUsername := flag.String("smtp-username", "", "SMTP username; empty disables authentication")
+	smtpPassword := flag.String("smtp-password", "", "SMTP password")
+	smtpFrom := flag.String("smtp-from", "", "sender address for outgoing email, e.g. \"BitRiver Live <noreply@example.com>\"")
+	smtpTLS := flag.String("smtp-tls", "", "SMTP encryption: starttls (default), tls, or none")
+	mailQueueSize := flag.Int("mail-queue-size", 0, "maximum emails waiting for delivery")
+	mailRecipientLimit := flag.Int("mail-recipient-limit", 0, "maximum emails sent to one address per hour")
+	clamdAddress := flag.String("clamd-address", "", "ClamAV daemon host:port that scans uploaded files before transcoding; empty disables scanning")
+	uploadScanTimeout := flag.Duration("upload-scan-timeout", 0, "maximum time to scan one uploaded file (default 2m)")
+	uploadScanFailOpen := flag.Bool("upload-scan-fail-open", false, "process uploads unscanned when the scanner is unavailable or times out instead of failing them")
 	// OAuth flags (env: BITRIVER_LIVE_OAUTH_CONFIG, BITRIVER_LIVE_OAUTH_PROVIDERS, BITRIVER_LIVE_OAUTH_* overrides).
 	oauthProvidersFlag := flag.String("oauth-providers", "", "JSON array or path describing OAuth providers")
 	var oauthClientIDs keyValueFlag
@@ -158,13 +238,31 @@ func main() {
 	flag.Var(&oauthClientIDs, "oauth-client-id", "override OAuth client ID (provider=value)")
 	flag.Var(&oauthClientSecrets, "oauth-client-secret", "override OAuth client secret (provider=value)")
 	flag.Var(&oauthRedirects, "oauth-redirect-url", "override OAuth redirect URL (provider=value)")
+	oauthReturnOrigins := flag.String("oauth-return-origins", "", "comma separated origins, besides this site, that OAuth sign-ins may return to (env: BITRIVER_LIVE_OAUTH_RETURN_ORIGINS)")
 	flag.Parse()
 
-	logger := logging.Init(logging.Config{Level: firstNonEmpty(*logLevel, os.Getenv("BITRIVER_LIVE_LOG_LEVEL")), Format: string(logging.FormatJSON)})
-	auditLogger := logging.WithComponent(logger, "audit")
+	logLevels := logging.NewLevelController(firstNonEmpty(*logLevel, os.Getenv("BITRIVER_LIVE_LOG_LEVEL")))
+	logger := logging.Init(logging.Config{Levels: logLevels, Format: string(logging.FormatJSON)})
+	// Audit events are written at info whatever the runtime log levels are
+	// set to, so they get a logger of their own.
+	auditLogger := logging.WithComponent(logging.New(logging.Config{Level: "info", Format: string(logging.FormatJSON)}), "audit")
 	registry := metrics.NewRegistry()
 	recorder := registry.Recorder
 
+	// In --check-config mode configuration errors are collected into the
+	// report instead of exiting, so one run surfaces every problem.
+	checkOnly := *checkConfig
+	strictMode := resolveBool(*strictConfig, "BITRIVER_LIVE_STRICT_CONFIG")
+	var report configReport
+	configError := func(component, message string, err error) {
+		if checkOnly {
+			report.fail(component, err)
+			return
+		}
+		logger.Error(message, "error", err)
+		os.Exit(1)
+	}
+
 	allowSelfSignupValue := *allowSelfSignup
 	if env, ok := os.LookupEnv("BITRIVER_LIVE_ALLOW_SELF_SIGNUP"); ok {
 		if value, err := strconv.ParseBool(strings.TrimSpace(env)); err == nil {
@@ -174,15 +272,30 @@ func main() {
 		}
 	}
 
-	_, oauthManager, err := oauth.LoadFromFlagsAndEnv(oauth.LoadInput{
+	oauthProviders, oauthManager, err := oauth.LoadFromFlagsAndEnv(oauth.LoadInput{
 		Source:        *oauthProvidersFlag,
 		ClientIDs:     oauthClientIDs,
 		ClientSecrets: oauthClientSecrets,
 		RedirectURLs:  oauthRedirects,
+		ReturnOrigins: splitAndTrim(*oauthReturnOrigins),
 	})
 	if err != nil {
-		logger.Error("failed to configure oauth", "error", err)
-		os.Exit(1)
+		configError("oauth", "failed to configure oauth", err)
+	}
+
+	maintenanceCfg := server.MaintenanceConfig{
+		Enabled: resolveBool(*maintenanceMode, "BITRIVER_LIVE_MAINTENANCE_MODE"),
+		Reason:  firstNonEmpty(*maintenanceReason, os.Getenv("BITRIVER_LIVE_MAINTENANCE_REASON")),
+	}
+
+	legacyAPISunsetValue, err := resolveLegacyAPISunset(*legacyAPISunset, os.Getenv("BITRIVER_LIVE_LEGACY_API_SUNSET"))
+	if err != nil {
+		configError("legacy-api-sunset", "invalid legacy API sunset", err)
+	}
+
+	readCacheCfg := server.ReadCacheConfig{
+		Disabled: resolveBool(*readCacheDisabled, "BITRIVER_LIVE_READ_CACHE_DISABLED"),
+		TTL:      resolveDuration(*readCacheTTL, "BITRIVER_LIVE_READ_CACHE_TTL", 0),
 	}
 
 	serverMode := modeValue(*mode, os.Getenv("BITRIVER_LIVE_MODE"))
@@ -192,11 +305,11 @@ func main() {
 
 	tlsCertPath := firstNonEmpty(*tlsCert, os.Getenv("BITRIVER_LIVE_TLS_CERT"))
 	tlsKeyPath := firstNonEmpty(*tlsKey, os.Getenv("BITRIVER_LIVE_TLS_KEY"))
+	tlsClientCAPath := firstNonEmpty(*tlsClientCA, os.Getenv("BITRIVER_LIVE_TLS_CLIENT_CA"))
 
 	viewerURL, err := resolveViewerOrigin(*viewerOrigin, os.Getenv("BITRIVER_VIEWER_ORIGIN"))
 	if err != nil {
-		logger.Error("invalid viewer origin", "error", err)
-		os.Exit(1)
+		configError("viewer-origin", "invalid viewer origin", err)
 	}
 
 	corsConfig := server.CORSConfig{
@@ -207,6 +320,7 @@ func main() {
 	securityCfg := server.SecurityConfig{
 		ContentSecurityPolicy: firstNonEmpty(*securityCSP, os.Getenv("BITRIVER_LIVE_SECURITY_CSP")),
 		FrameAncestors:        firstNonEmpty(*securityFrameAncestors, os.Getenv("BITRIVER_LIVE_SECURITY_FRAME_ANCESTORS")),
+		EmbedFrameAncestors:   firstNonEmpty(*securityEmbedFrameAncestors, os.Getenv("BITRIVER_LIVE_SECURITY_EMBED_FRAME_ANCESTORS")),
 		FrameOptions:          firstNonEmpty(*securityFrameOptions, os.Getenv("BITRIVER_LIVE_SECURITY_FRAME_OPTIONS")),
 		ReferrerPolicy:        firstNonEmpty(*securityReferrerPolicy, os.Getenv("BITRIVER_LIVE_SECURITY_REFERRER_POLICY")),
 		PermissionsPolicy:     firstNonEmpty(*securityPermissionsPolicy, os.Getenv("BITRIVER_LIVE_SECURITY_PERMISSIONS_POLICY")),
@@ -215,8 +329,7 @@ func main() {
 
 	ingestConfig, err := ingest.LoadConfigFromEnv()
 	if err != nil {
-		logger.Error("failed to load ingest configuration", "error", err)
-		os.Exit(1)
+		configError("ingest", "failed to load ingest configuration", err)
 	}
 
 	var (
@@ -229,23 +342,24 @@ func main() {
 	if ingestConfig.Enabled() {
 		controller, err := ingestConfig.NewHTTPController()
 		if err != nil {
-			logger.Error("failed to initialise ingest controller", "error", err)
-			os.Exit(1)
+			configError("ingest", "failed to initialise ingest controller", err)
+		} else {
+			controller.SetLogger(logging.WithComponent(logger, "ingest"))
+			ingestController = controller
+			options = append(options, storage.WithIngestController(controller))
 		}
-		controller.SetLogger(logging.WithComponent(logger, "ingest"))
-		ingestController = controller
-		options = append(options, storage.WithIngestController(controller))
+	}
+	if timeout := resolveDuration(*startingTimeout, "BITRIVER_LIVE_STARTING_TIMEOUT", 0); timeout > 0 {
+		options = append(options, storage.WithStartingTimeout(timeout))
 	}
 
 	publishedRetention, publishedSet, err := resolveDurationSetting(*recordingRetentionPublished, "BITRIVER_LIVE_RECORDING_RETENTION_PUBLISHED")
 	if err != nil {
-		logger.Error("invalid published retention", "error", err)
-		os.Exit(1)
+		configError("recording-retention", "invalid published retention", err)
 	}
 	unpublishedRetention, unpublishedSet, err := resolveDurationSetting(*recordingRetentionUnpublished, "BITRIVER_LIVE_RECORDING_RETENTION_UNPUBLISHED")
 	if err != nil {
-		logger.Error("invalid unpublished retention", "error", err)
-		os.Exit(1)
+		configError("recording-retention", "invalid unpublished retention", err)
 	}
 	if publishedSet || unpublishedSet {
 		policy := storage.RecordingRetentionPolicy{Published: -1, Unpublished: -1}
@@ -257,6 +371,12 @@ func main() {
 		}
 		options = append(options, storage.WithRecordingRetention(policy))
 	}
+	if quotaMB := resolveInt(*channelStorageQuotaMB, "BITRIVER_LIVE_CHANNEL_STORAGE_QUOTA_MB"); quotaMB > 0 {
+		options = append(options, storage.WithChannelStorageQuota(int64(quotaMB)<<20))
+	}
+	if limit := resolveInt(*maxConcurrentStreams, "BITRIVER_LIVE_MAX_CONCURRENT_STREAMS"); limit > 0 {
+		options = append(options, storage.WithMaxConcurrentStreams(limit))
+	}
 
 	objectCfg := storage.ObjectStorageConfig{
 		Endpoint:       firstNonEmpty(*objectEndpoint, os.Getenv("BITRIVER_LIVE_OBJECT_ENDPOINT")),
@@ -268,39 +388,193 @@ func main() {
 		Prefix:         strings.TrimSpace(firstNonEmpty(*objectPrefix, os.Getenv("BITRIVER_LIVE_OBJECT_PREFIX"))),
 		PublicEndpoint: firstNonEmpty(*objectPublicEndpoint, os.Getenv("BITRIVER_LIVE_OBJECT_PUBLIC_ENDPOINT")),
 		LifecycleDays:  resolveInt(*objectLifecycleDays, "BITRIVER_LIVE_OBJECT_LIFECYCLE_DAYS"),
+		RequestTimeout: resolveDuration(*objectRequestTimeout, "BITRIVER_LIVE_OBJECT_REQUEST_TIMEOUT", 0),
+		UploadTimeout:  resolveDuration(*objectUploadTimeout, "BITRIVER_LIVE_OBJECT_UPLOAD_TIMEOUT", 0),
+		MaxRetries:     resolveInt(*objectMaxRetries, "BITRIVER_LIVE_OBJECT_MAX_RETRIES"),
+
+		RestrictProfileImages: resolveBool(*objectRestrictProfileImages, "BITRIVER_LIVE_OBJECT_RESTRICT_PROFILE_IMAGES"),
+	}
+	if thresholdMB := resolveInt(*objectMultipartThresholdMB, "BITRIVER_LIVE_OBJECT_MULTIPART_THRESHOLD_MB"); thresholdMB > 0 {
+		objectCfg.MultipartThreshold = int64(thresholdMB) << 20
 	}
 	if objectCfg.Endpoint != "" || objectCfg.Bucket != "" || objectCfg.PublicEndpoint != "" || objectCfg.Prefix != "" || objectCfg.Region != "" || objectCfg.AccessKey != "" || objectCfg.SecretKey != "" || objectCfg.LifecycleDays > 0 || objectCfg.UseSSL {
 		options = append(options, storage.WithObjectStorage(objectCfg))
 	}
 
+	var serverSecretKey []byte
+	if raw := firstNonEmpty(*secretKey, os.Getenv("BITRIVER_LIVE_SECRET_KEY")); raw != "" {
+		key, err := storage.ParseSecretKey(raw)
+		if err != nil {
+			configError("secret-key", "invalid secret key", err)
+		} else {
+			options = append(options, storage.WithSecretKey(key))
+			serverSecretKey = key
+		}
+	}
+	if raw := firstNonEmpty(*encryptionKeys, os.Getenv("BITRIVER_LIVE_ENCRYPTION_KEYS")); raw != "" {
+		keys, err := storage.ParseEncryptionKeys(raw)
+		if err != nil {
+			configError("encryption-keys", "invalid encryption keys", err)
+		} else {
+			options = append(options, storage.WithEncryptionKeys(keys))
+		}
+	}
+
 	postgresDefaultDSN := resolvePostgresDSN(*postgresDSN)
 	driver, _, err := resolveStorageDriver(*storageDriver, os.Getenv("BITRIVER_LIVE_STORAGE_DRIVER"), postgresDefaultDSN)
 	if err != nil {
-		logger.Error("failed to resolve storage driver", "error", err)
-		os.Exit(1)
+		configError("datastore", "failed to resolve storage driver", err)
 	}
 	if serverMode == "production" {
 		if err := validateProductionDatastore(driver, postgresDefaultDSN, os.Getenv("BITRIVER_LIVE_POSTGRES_DSN")); err != nil {
-			logger.Error("production datastore validation failed", "error", err)
-			os.Exit(1)
+			configError("datastore", "production datastore validation failed", err)
 		}
 	}
 	var (
-		store                   storage.Repository
-		storagePostgresDSN      string
-		datastoreAcquireTimeout time.Duration
-		dataFile                string
+		storagePostgresDSN string
+		dataFile           string
 	)
 	switch driver {
 	case "json":
 		dataFile = resolveDataPath(*dataPath, os.Getenv("BITRIVER_LIVE_DATA"))
-		store, err = storage.NewJSONRepository(dataFile, options...)
 	case "postgres":
 		storagePostgresDSN = postgresDefaultDSN
 		if storagePostgresDSN == "" {
-			logger.Error("postgres storage selected without DSN")
+			configError("datastore", "postgres storage selected without DSN", errors.New("postgres storage selected without DSN"))
+		}
+	}
+	datastoreAcquireTimeout := resolveDuration(*postgresAcquireTimeout, "BITRIVER_LIVE_POSTGRES_ACQUIRE_TIMEOUT", 0)
+
+	sessionConfig, err := resolveSessionStoreConfig(
+		*sessionStoreDriver,
+		os.Getenv("BITRIVER_LIVE_SESSION_STORE"),
+		driver,
+		storagePostgresDSN,
+		*sessionPostgresDSN,
+		os.Getenv("BITRIVER_LIVE_SESSION_POSTGRES_DSN"),
+		serverMode == "production",
+	)
+	if err != nil {
+		configError("session-store", "failed to resolve session store", err)
+	}
+
+	sessionAbsoluteTTL := resolveDuration(*sessionTTL, "BITRIVER_LIVE_SESSION_TTL", 0)
+	sessionIdleTimeoutValue := resolveDuration(*sessionIdleTimeout, "BITRIVER_LIVE_SESSION_IDLE_TIMEOUT", 0)
+	sessionConfig.AbsoluteTTL = sessionAbsoluteTTL
+	sessionConfig.IdleTimeout = sessionIdleTimeoutValue
+	if sessionConfig.Driver == "redis" {
+		sessionConfig.Redis = auth.RedisSessionStoreConfig{
+			Addr:       firstNonEmpty(*sessionRedisAddr, os.Getenv("BITRIVER_LIVE_SESSION_REDIS_ADDR")),
+			Addrs:      splitAndTrim(firstNonEmpty(*sessionRedisAddrs, os.Getenv("BITRIVER_LIVE_SESSION_REDIS_ADDRS"))),
+			Username:   firstNonEmpty(*sessionRedisUsername, os.Getenv("BITRIVER_LIVE_SESSION_REDIS_USERNAME")),
+			Password:   firstNonEmpty(*sessionRedisPassword, os.Getenv("BITRIVER_LIVE_SESSION_REDIS_PASSWORD")),
+			MasterName: firstNonEmpty(*sessionRedisMasterName, os.Getenv("BITRIVER_LIVE_SESSION_REDIS_SENTINEL_MASTER")),
+			PoolSize:   resolveInt(*sessionRedisPoolSize, "BITRIVER_LIVE_SESSION_REDIS_POOL_SIZE"),
+			Timeout:    resolveDuration(*sessionRedisTimeout, "BITRIVER_LIVE_SESSION_REDIS_TIMEOUT", 0),
+			TLS: auth.RedisTLSConfig{
+				CAFile:             firstNonEmpty(*sessionRedisTLSCA, os.Getenv("BITRIVER_LIVE_SESSION_REDIS_TLS_CA")),
+				CertFile:           firstNonEmpty(*sessionRedisTLSCert, os.Getenv("BITRIVER_LIVE_SESSION_REDIS_TLS_CERT")),
+				KeyFile:            firstNonEmpty(*sessionRedisTLSKey, os.Getenv("BITRIVER_LIVE_SESSION_REDIS_TLS_KEY")),
+				ServerName:         firstNonEmpty(*sessionRedisTLSServerName, os.Getenv("BITRIVER_LIVE_SESSION_REDIS_TLS_SERVER_NAME")),
+				InsecureSkipVerify: resolveBool(*sessionRedisTLSSkipVerify, "BITRIVER_LIVE_SESSION_REDIS_TLS_SKIP_VERIFY"),
+			},
+		}
+		if len(sessionConfig.Redis.Addrs) == 0 && strings.TrimSpace(sessionConfig.Redis.Addr) == "" {
+			configError("session-store", "redis session store selected without an address", errors.New("redis session store selected without an address; set --session-redis-addr or BITRIVER_LIVE_SESSION_REDIS_ADDR"))
+		}
+	}
+
+	chatQueueCfg := chat.RedisQueueConfig{
+		Addr:       firstNonEmpty(*chatRedisAddr, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_ADDR")),
+		Addrs:      splitAndTrim(firstNonEmpty(*chatRedisAddrs, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_ADDRS"))),
+		Username:   firstNonEmpty(*chatRedisUsername, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_USERNAME")),
+		Password:   firstNonEmpty(*chatRedisPassword, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_PASSWORD")),
+		Stream:     firstNonEmpty(*chatRedisStream, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_STREAM")),
+		Group:      firstNonEmpty(*chatRedisGroup, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_GROUP")),
+		MasterName: firstNonEmpty(*chatRedisMasterName, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_SENTINEL_MASTER")),
+		PoolSize:   resolveInt(*chatRedisPoolSize, "BITRIVER_LIVE_CHAT_QUEUE_REDIS_POOL_SIZE"),
+		TLS: chat.RedisTLSConfig{
+			CAFile:             firstNonEmpty(*chatRedisTLSCA, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_TLS_CA")),
+			CertFile:           firstNonEmpty(*chatRedisTLSCert, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_TLS_CERT")),
+			KeyFile:            firstNonEmpty(*chatRedisTLSKey, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_TLS_KEY")),
+			ServerName:         firstNonEmpty(*chatRedisTLSServerName, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_TLS_SERVER_NAME")),
+			InsecureSkipVerify: resolveBool(*chatRedisTLSSkipVerify, "BITRIVER_LIVE_CHAT_QUEUE_REDIS_TLS_SKIP_VERIFY"),
+		},
+		DeadLetterStream: firstNonEmpty(*chatRedisDeadLetterStream, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_DEAD_LETTER_STREAM")),
+		MaxDeliveries:    resolveInt(*chatQueueMaxDeliveries, "BITRIVER_LIVE_CHAT_QUEUE_MAX_DELIVERIES"),
+		ClaimIdle:        resolveDuration(*chatQueueRetryDelay, "BITRIVER_LIVE_CHAT_QUEUE_RETRY_DELAY", chat.DefaultRetryDelay),
+		Metrics:          recorder,
+	}
+	chatDriver := resolveChatQueueDriver(*chatQueueDriver, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_DRIVER"))
+
+	rateCfg := server.RateLimitConfig{
+		GlobalRPS:             resolveFloat(*globalRPS, "BITRIVER_LIVE_RATE_GLOBAL_RPS"),
+		GlobalBurst:           resolveInt(*globalBurst, "BITRIVER_LIVE_RATE_GLOBAL_BURST"),
+		LoginLimit:            resolveInt(*loginLimit, "BITRIVER_LIVE_RATE_LOGIN_LIMIT"),
+		LoginWindow:           resolveDuration(*loginWindow, "BITRIVER_LIVE_RATE_LOGIN_WINDOW", time.Minute),
+		TrustForwardedHeaders: resolveBool(*trustForwarded, "BITRIVER_LIVE_RATE_TRUST_FORWARDED_HEADERS"),
+		TrustedProxies:        splitAndTrim(firstNonEmpty(*trustedProxies, os.Getenv("BITRIVER_LIVE_RATE_TRUSTED_PROXIES"))),
+		RedisAddr:             firstNonEmpty(*redisAddr, os.Getenv("BITRIVER_LIVE_RATE_REDIS_ADDR")),
+		RedisAddrs:            splitAndTrim(firstNonEmpty(*redisAddrs, os.Getenv("BITRIVER_LIVE_RATE_REDIS_ADDRS"))),
+		RedisUsername:         firstNonEmpty(*redisUsername, os.Getenv("BITRIVER_LIVE_RATE_REDIS_USERNAME")),
+		RedisPassword:         firstNonEmpty(*redisPassword, os.Getenv("BITRIVER_LIVE_RATE_REDIS_PASSWORD")),
+		RedisMasterName:       firstNonEmpty(*redisMasterName, os.Getenv("BITRIVER_LIVE_RATE_REDIS_MASTER_NAME")),
+		RedisTimeout:          resolveDuration(*redisTimeout, "BITRIVER_LIVE_RATE_REDIS_TIMEOUT", 2*time.Second),
+		RedisPoolSize:         resolveInt(*redisPoolSize, "BITRIVER_LIVE_RATE_REDIS_POOL_SIZE"),
+		RedisTLS: server.RedisTLSConfig{
+			CAFile:             firstNonEmpty(*redisTLSCA, os.Getenv("BITRIVER_LIVE_RATE_REDIS_TLS_CA")),
+			CertFile:           firstNonEmpty(*redisTLSCert, os.Getenv("BITRIVER_LIVE_RATE_REDIS_TLS_CERT")),
+			KeyFile:            firstNonEmpty(*redisTLSKey, os.Getenv("BITRIVER_LIVE_RATE_REDIS_TLS_KEY")),
+			ServerName:         firstNonEmpty(*redisTLSServerName, os.Getenv("BITRIVER_LIVE_RATE_REDIS_TLS_SERVER_NAME")),
+			InsecureSkipVerify: resolveBool(*redisTLSSkipVerify, "BITRIVER_LIVE_RATE_REDIS_TLS_SKIP_VERIFY"),
+		},
+	}
+
+	tlsCfg := server.TLSConfig{
+		CertFile:       tlsCertPath,
+		KeyFile:        tlsKeyPath,
+		ReloadInterval: resolveDuration(*tlsReloadInterval, "BITRIVER_LIVE_TLS_RELOAD_INTERVAL", 0),
+		ClientCAFile:   tlsClientCAPath,
+	}
+
+	if checkOnly || strictMode {
+		checkInput := configCheckInput{
+			OAuthProviders: oauthProviders,
+			StorageDriver:  driver,
+			StorageDSN:     storagePostgresDSN,
+			DataPath:       dataFile,
+			Session:        sessionConfig,
+			TLS:            tlsCfg,
+			ObjectStorage:  objectCfg,
+			ChatDriver:     chatDriver,
+			ChatQueue:      chatQueueCfg,
+			RateLimit:      rateCfg,
+		}
+		if ingestController != nil {
+			checkInput.IngestHealth = ingestController.HealthChecks
+		}
+		report.add(runConfigChecks(context.Background(), checkInput, newConfigProbes(configCheckTimeout), configCheckTimeout, time.Now()).Checks...)
+		if checkOnly {
+			if err := report.Write(os.Stdout); err != nil {
+				logger.Error("failed to write configuration report", "error", err)
+			}
+			if report.Failed() {
+				os.Exit(1)
+			}
+			os.Exit(0)
+		}
+		logConfigReport(logger, report)
+		if report.Failed() {
+			logger.Error("strict configuration check failed; refusing to start")
 			os.Exit(1)
 		}
+	}
+
+	var store storage.Repository
+	switch driver {
+	case "json":
+		store, err = storage.NewJSONRepository(dataFile, options...)
+	case "postgres":
 		pgOptions := append([]storage.Option(nil), options...)
 		maxConns := resolveInt(*postgresMaxConns, "BITRIVER_LIVE_POSTGRES_MAX_CONNS")
 		minConns := resolveInt(*postgresMinConns, "BITRIVER_LIVE_POSTGRES_MIN_CONNS")
@@ -313,15 +587,30 @@ func main() {
 		if maxLifetime > 0 || maxIdle > 0 || healthInterval > 0 {
 			pgOptions = append(pgOptions, storage.WithPostgresPoolDurations(maxLifetime, maxIdle, healthInterval))
 		}
-		acquireTimeout := resolveDuration(*postgresAcquireTimeout, "BITRIVER_LIVE_POSTGRES_ACQUIRE_TIMEOUT", 0)
-		datastoreAcquireTimeout = acquireTimeout
-		if acquireTimeout > 0 {
-			pgOptions = append(pgOptions, storage.WithPostgresAcquireTimeout(acquireTimeout))
+		if datastoreAcquireTimeout > 0 {
+			pgOptions = append(pgOptions, storage.WithPostgresAcquireTimeout(datastoreAcquireTimeout))
+		}
+		if statementTimeout := resolveDuration(*postgresStatementTimeout, "BITRIVER_LIVE_POSTGRES_STATEMENT_TIMEOUT", 0); statementTimeout > 0 {
+			pgOptions = append(pgOptions, storage.WithPostgresStatementTimeout(statementTimeout))
+		}
+		txRetries := *postgresTxRetries
+		if txRetries < 0 {
+			if env := os.Getenv("BITRIVER_LIVE_POSTGRES_TX_RETRIES"); env != "" {
+				if value, err := parseInt(env); err == nil {
+					txRetries = value
+				}
+			}
+		}
+		if txRetries >= 0 {
+			pgOptions = append(pgOptions, storage.WithPostgresTxRetries(txRetries))
 		}
 		appName := firstNonEmpty(*postgresAppName, os.Getenv("BITRIVER_LIVE_POSTGRES_APP_NAME"))
 		if appName != "" {
 			pgOptions = append(pgOptions, storage.WithPostgresApplicationName(appName))
 		}
+		if replicas := splitAndTrim(firstNonEmpty(*postgresReplicaDSNs, os.Getenv("BITRIVER_LIVE_POSTGRES_REPLICA_DSNS"))); len(replicas) > 0 {
+			pgOptions = append(pgOptions, storage.WithPostgresReplicas(replicas...))
+		}
 		store, err = storage.NewPostgresRepository(storagePostgresDSN, pgOptions...)
 	default:
 		logger.Error("unsupported storage driver", "driver", driver)
@@ -331,25 +620,10 @@ func main() {
 		logger.Error("failed to open datastore", "error", err)
 		os.Exit(1)
 	}
-
-	sessionConfig, err := resolveSessionStoreConfig(
-		*sessionStoreDriver,
-		os.Getenv("BITRIVER_LIVE_SESSION_STORE"),
-		driver,
-		storagePostgresDSN,
-		*sessionPostgresDSN,
-		os.Getenv("BITRIVER_LIVE_SESSION_POSTGRES_DSN"),
-		serverMode == "production",
-	)
-	if err != nil {
-		logger.Error("failed to resolve session store", "error", err)
-		os.Exit(1)
-	}
-
-	sessionAbsoluteTTL := resolveDuration(*sessionTTL, "BITRIVER_LIVE_SESSION_TTL", 0)
-	sessionIdleTimeoutValue := resolveDuration(*sessionIdleTimeout, "BITRIVER_LIVE_SESSION_IDLE_TIMEOUT", 0)
-	sessionConfig.AbsoluteTTL = sessionAbsoluteTTL
-	sessionConfig.IdleTimeout = sessionIdleTimeoutValue
+	eventBus := events.NewBus(events.Config{Logger: logging.WithComponent(logger, "events")})
+	events.Subscribe(eventBus, "stream-metrics", func(events.StreamStarted) { metrics.StreamStarted() })
+	events.Subscribe(eventBus, "stream-metrics", func(events.StreamStopped) { metrics.StreamStopped() })
+	store = storage.NewEventRepository(store, eventBus)
 
 	var (
 		sessionStore  auth.SessionStore
@@ -367,34 +641,24 @@ func main() {
 		}
 		sessionStore = pgStore
 		sessionCloser = func(ctx context.Context) error { return pgStore.Close(ctx) }
+	case "redis":
+		redisStore, err := auth.NewRedisSessionStore(sessionConfig.Redis)
+		if err != nil {
+			logger.Error("failed to open session store", "error", err)
+			os.Exit(1)
+		}
+		sessionStore = redisStore
+		sessionCloser = func(ctx context.Context) error { return redisStore.Close(ctx) }
 	default:
 		logger.Error("unsupported session store driver", "driver", sessionConfig.Driver)
 		os.Exit(1)
 	}
 
-	sessionOptions := []auth.SessionOption{auth.WithStore(sessionStore)}
+	sessionOptions := []auth.SessionOption{auth.WithStore(sessionStore), auth.WithAuditLogger(auditLogger)}
 	if sessionIdleTimeoutValue > 0 {
 		sessionOptions = append(sessionOptions, auth.WithIdleTimeout(sessionIdleTimeoutValue))
 	}
 	sessions := auth.NewSessionManager(sessionAbsoluteTTL, sessionOptions...)
-	chatQueueCfg := chat.RedisQueueConfig{
-		Addr:       firstNonEmpty(*chatRedisAddr, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_ADDR")),
-		Addrs:      splitAndTrim(firstNonEmpty(*chatRedisAddrs, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_ADDRS"))),
-		Username:   firstNonEmpty(*chatRedisUsername, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_USERNAME")),
-		Password:   firstNonEmpty(*chatRedisPassword, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_PASSWORD")),
-		Stream:     firstNonEmpty(*chatRedisStream, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_STREAM")),
-		Group:      firstNonEmpty(*chatRedisGroup, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_GROUP")),
-		MasterName: firstNonEmpty(*chatRedisMasterName, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_SENTINEL_MASTER")),
-		PoolSize:   resolveInt(*chatRedisPoolSize, "BITRIVER_LIVE_CHAT_QUEUE_REDIS_POOL_SIZE"),
-		TLS: chat.RedisTLSConfig{
-			CAFile:             firstNonEmpty(*chatRedisTLSCA, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_TLS_CA")),
-			CertFile:           firstNonEmpty(*chatRedisTLSCert, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_TLS_CERT")),
-			KeyFile:            firstNonEmpty(*chatRedisTLSKey, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_TLS_KEY")),
-			ServerName:         firstNonEmpty(*chatRedisTLSServerName, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_TLS_SERVER_NAME")),
-			InsecureSkipVerify: resolveBool(*chatRedisTLSSkipVerify, "BITRIVER_LIVE_CHAT_QUEUE_REDIS_TLS_SKIP_VERIFY"),
-		},
-	}
-	chatDriver := resolveChatQueueDriver(*chatQueueDriver, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_DRIVER"))
 	queue, err := configureChatQueue(chatDriver, chatQueueCfg, logger)
 	if err != nil {
 		logger.Error("failed to configure chat queue", "error", err)
@@ -405,64 +669,159 @@ func main() {
 		Store:  store,
 		Logger: logging.WithComponent(logger, "chat"),
 	})
+	componentHealth := health.NewRegistry(health.Config{
+		StaleAfter: resolveDuration(*componentStaleAfter, "BITRIVER_LIVE_COMPONENT_STALE_AFTER", health.DefaultStaleAfter),
+	})
 	handler := api.NewHandler(store, sessions)
 	handler.AllowSelfSignup = allowSelfSignupValue
+	handler.ComponentHealth = componentHealth
+	handler.ReadyRequiresComponents = resolveBool(*readyRequiresComponents, "BITRIVER_LIVE_READY_REQUIRES_COMPONENTS")
 	handler.ChatGateway = gateway
 	handler.DefaultRenditions = ladderProfileNames(ingestConfig.LadderProfiles)
+	handler.LadderProfiles = append([]ingest.Rendition(nil), ingestConfig.LadderProfiles...)
+	handler.TranscodeLimits = ingestConfig.TranscodeLimits
 	handler.SRSHookToken = ingestConfig.SRSToken
+	handler.CookieSigningKey = serverSecretKey
+	handler.LegacyAPISunset = legacyAPISunsetValue
+	if key := firstNonEmpty(*playbackSigningKey, os.Getenv("BITRIVER_LIVE_PLAYBACK_SIGNING_KEY")); key != "" {
+		signer, err := auth.NewPlaybackSigner(auth.PlaybackSignerConfig{
+			Key:          key,
+			PreviousKeys: splitAndTrim(firstNonEmpty(*playbackSigningKeyPrevious, os.Getenv("BITRIVER_LIVE_PLAYBACK_SIGNING_KEY_PREVIOUS"))),
+			TTL:          resolveDuration(*playbackTokenTTL, "BITRIVER_LIVE_PLAYBACK_TOKEN_TTL", auth.DefaultPlaybackTokenTTL),
+		})
+		if err != nil {
+			logger.Error("failed to configure playback signing", "error", err)
+			os.Exit(1)
+		}
+		handler.PlaybackSigner = signer
+	}
 	if pingable, ok := queue.(interface{ Ping(context.Context) error }); ok {
 		handler.ChatQueue = pingable
 	}
+	mailSender, err := newMailSender(*smtpHost, *smtpPort, *smtpUsername, *smtpPassword, *smtpFrom, *smtpTLS, logging.WithComponent(logger, "mail"))
+	if err != nil {
+		logger.Error("failed to configure email", "error", err)
+		os.Exit(1)
+	}
+	mailQueue := mail.NewQueue(mail.QueueConfig{
+		Sender:         mailSender,
+		Size:           resolveInt(*mailQueueSize, "BITRIVER_LIVE_MAIL_QUEUE_SIZE"),
+		RecipientLimit: resolveInt(*mailRecipientLimit, "BITRIVER_LIVE_MAIL_RECIPIENT_LIMIT"),
+		Logger:         logging.WithComponent(logger, "mail"),
+		Metrics:        recorder,
+	})
+	handler.Mail = mailQueue
 	var uploadProcessor *api.UploadProcessor
 	if ingestController != nil {
+		scanTimeout := resolveDuration(*uploadScanTimeout, "BITRIVER_LIVE_UPLOAD_SCAN_TIMEOUT", 0)
+		scanner, err := newUploadScanner(*clamdAddress, scanTimeout, logging.WithComponent(logger, "uploads"))
+		if err != nil {
+			logger.Error("failed to configure upload scanning", "error", err)
+			os.Exit(1)
+		}
 		uploadProcessor = api.NewUploadProcessor(api.UploadProcessorConfig{
-			Store:      api.RepositoryUploadStore(store),
-			Ingest:     ingestController,
-			Renditions: ingestConfig.LadderProfiles,
-			Logger:     logging.WithComponent(logger, "uploads"),
+			Store:          api.RepositoryUploadStore(store),
+			Ingest:         ingestController,
+			Renditions:     ingestConfig.LadderProfiles,
+			Logger:         logging.WithComponent(logger, "uploads"),
+			Health:         componentHealth,
+			Scanner:        scanner,
+			ScanTimeout:    scanTimeout,
+			ScanFailOpen:   resolveBool(*uploadScanFailOpen, "BITRIVER_LIVE_UPLOAD_SCAN_FAIL_OPEN"),
+			Sources:        handler.UploadSources(),
+			SecurityEvents: store,
+			AuditLogger:    auditLogger,
 		})
 		uploadProcessor.Start()
 		handler.UploadProcessor = uploadProcessor
 	}
 	workerCtx, workerCancel := context.WithCancel(context.Background())
 	defer workerCancel()
-	sessionPurgeStop := startSessionPurgeWorker(workerCtx, logging.WithComponent(logger, "session-purger"), sessions, 15*time.Minute)
+	sessionPurgeInterval := 15 * time.Minute
+	sessionPurgeStop := startSessionPurgeWorker(workerCtx, logging.WithComponent(logger, "session-purger"), sessions, sessionPurgeInterval,
+		componentHealth.RegisterComponent("session-purger", health.WithStaleAfter(2*sessionPurgeInterval)))
 	defer sessionPurgeStop()
-	go storage.NewChatWorker(store, queue, logging.WithComponent(logger, "chat-worker")).Run(workerCtx)
-
-	rateCfg := server.RateLimitConfig{
-		GlobalRPS:             resolveFloat(*globalRPS, "BITRIVER_LIVE_RATE_GLOBAL_RPS"),
-		GlobalBurst:           resolveInt(*globalBurst, "BITRIVER_LIVE_RATE_GLOBAL_BURST"),
-		LoginLimit:            resolveInt(*loginLimit, "BITRIVER_LIVE_RATE_LOGIN_LIMIT"),
-		LoginWindow:           resolveDuration(*loginWindow, "BITRIVER_LIVE_RATE_LOGIN_WINDOW", time.Minute),
-		TrustForwardedHeaders: resolveBool(*trustForwarded, "BITRIVER_LIVE_RATE_TRUST_FORWARDED_HEADERS"),
-		TrustedProxies:        splitAndTrim(firstNonEmpty(*trustedProxies, os.Getenv("BITRIVER_LIVE_RATE_TRUSTED_PROXIES"))),
-		RedisAddr:             firstNonEmpty(*redisAddr, os.Getenv("BITRIVER_LIVE_RATE_REDIS_ADDR")),
-		RedisAddrs:            splitAndTrim(firstNonEmpty(*redisAddrs, os.Getenv("BITRIVER_LIVE_RATE_REDIS_ADDRS"))),
-		RedisUsername:         firstNonEmpty(*redisUsername, os.Getenv("BITRIVER_LIVE_RATE_REDIS_USERNAME")),
-		RedisPassword:         firstNonEmpty(*redisPassword, os.Getenv("BITRIVER_LIVE_RATE_REDIS_PASSWORD")),
-		RedisMasterName:       firstNonEmpty(*redisMasterName, os.Getenv("BITRIVER_LIVE_RATE_REDIS_MASTER_NAME")),
-		RedisTimeout:          resolveDuration(*redisTimeout, "BITRIVER_LIVE_RATE_REDIS_TIMEOUT", 2*time.Second),
-		RedisPoolSize:         resolveInt(*redisPoolSize, "BITRIVER_LIVE_RATE_REDIS_POOL_SIZE"),
-		RedisTLS: server.RedisTLSConfig{
-			CAFile:             firstNonEmpty(*redisTLSCA, os.Getenv("BITRIVER_LIVE_RATE_REDIS_TLS_CA")),
-			CertFile:           firstNonEmpty(*redisTLSCert, os.Getenv("BITRIVER_LIVE_RATE_REDIS_TLS_CERT")),
-			KeyFile:            firstNonEmpty(*redisTLSKey, os.Getenv("BITRIVER_LIVE_RATE_REDIS_TLS_KEY")),
-			ServerName:         firstNonEmpty(*redisTLSServerName, os.Getenv("BITRIVER_LIVE_RATE_REDIS_TLS_SERVER_NAME")),
-			InsecureSkipVerify: resolveBool(*redisTLSSkipVerify, "BITRIVER_LIVE_RATE_REDIS_TLS_SKIP_VERIFY"),
-		},
+	go storage.NewChatWorker(store, queue, logging.WithComponent(logger, "chat-worker")).
+		WithHealth(componentHealth.RegisterComponent("chat-worker")).
+		WithBatching(resolveInt(*chatBatchSize, "BITRIVER_LIVE_CHAT_BATCH_SIZE"),
+			resolveDuration(*chatBatchWait, "BITRIVER_LIVE_CHAT_BATCH_WAIT", storage.DefaultChatBatchWait)).
+		Run(workerCtx)
+	jobPool := jobs.NewPool(jobs.Config{
+		Store:   store,
+		Workers: resolveInt(*jobWorkers, "BITRIVER_LIVE_JOB_WORKERS"),
+		Logger:  logging.WithComponent(logger, "jobs"),
+		Health:  componentHealth,
+	})
+	if err := jobPool.Register(jobs.RecordingRetentionJob, jobs.RecordingRetention(store)); err != nil {
+		logger.Error("failed to register recording retention job", "error", err)
+		os.Exit(1)
+	}
+	if err := jobPool.Schedule(jobs.RecordingRetentionJob, resolveDuration(*recordingRetentionInterval, "BITRIVER_LIVE_RECORDING_RETENTION_INTERVAL", time.Hour)); err != nil {
+		logger.Error("failed to schedule recording retention job", "error", err)
+		os.Exit(1)
+	}
+	statsEvery := resolveDuration(*statsRollupInterval, "BITRIVER_LIVE_STATS_ROLLUP_INTERVAL", 15*time.Minute)
+	if err := jobPool.Register(jobs.PlatformStatsJob, jobs.PlatformStatsRollup(store)); err != nil {
+		logger.Error("failed to register platform stats job", "error", err)
+		os.Exit(1)
+	}
+	if err := jobPool.Schedule(jobs.PlatformStatsJob, statsEvery); err != nil {
+		logger.Error("failed to schedule platform stats job", "error", err)
+		os.Exit(1)
+	}
+	if err := jobPool.Register(jobs.ChannelGrantSweepJob, jobs.ChannelGrantSweep(store, gateway)); err != nil {
+		logger.Error("failed to register channel grant sweep job", "error", err)
+		os.Exit(1)
+	}
+	if err := jobPool.Schedule(jobs.ChannelGrantSweepJob, time.Minute); err != nil {
+		logger.Error("failed to schedule channel grant sweep job", "error", err)
+		os.Exit(1)
 	}
+	if err := jobPool.Register(jobs.PremiereStartJob, jobs.PremiereStart(store)); err != nil {
+		logger.Error("failed to register premiere start job", "error", err)
+		os.Exit(1)
+	}
+	if err := jobPool.Schedule(jobs.PremiereStartJob, 10*time.Second); err != nil {
+		logger.Error("failed to schedule premiere start job", "error", err)
+		os.Exit(1)
+	}
+	ingestEventsTokenValue := firstNonEmpty(*ingestEventsToken, os.Getenv("BITRIVER_LIVE_INGEST_EVENTS_TOKEN"))
+	if idleTimeout := resolveDuration(*ingestIdleTimeout, "BITRIVER_LIVE_INGEST_IDLE_TIMEOUT", 2*time.Minute); ingestEventsTokenValue != "" && idleTimeout > 0 {
+		if err := jobPool.Register(jobs.IdleStreamStopJob, jobs.IdleStreamStop(store, idleTimeout)); err != nil {
+			logger.Error("failed to register idle stream job", "error", err)
+			os.Exit(1)
+		}
+		if err := jobPool.Schedule(jobs.IdleStreamStopJob, max(idleTimeout/4, 5*time.Second)); err != nil {
+			logger.Error("failed to schedule idle stream job", "error", err)
+			os.Exit(1)
+		}
+	}
+	if err := jobPool.Start(); err != nil {
+		logger.Error("failed to start job pool", "error", err)
+		os.Exit(1)
+	}
+	ingestHealthPoller := ingest.NewHealthPoller(ingest.HealthPollerConfig{
+		Controller: ingestController,
+		Record:     store.RecordIngestHealth,
+		Interval:   resolveDuration(*ingestHealthInterval, "BITRIVER_LIVE_INGEST_HEALTH_INTERVAL", 0),
+		MaxBackoff: resolveDuration(*ingestHealthMaxBackoff, "BITRIVER_LIVE_INGEST_HEALTH_MAX_BACKOFF", 0),
+		Logger:     logging.WithComponent(logger, "ingest-health"),
+	})
+	ingestHealthPoller.Start()
+	handler.IngestHealthPoller = ingestHealthPoller
+	handler.StreamRequestBudget = resolveDuration(*streamRequestBudget, "BITRIVER_LIVE_STREAM_REQUEST_BUDGET", 0)
+	handler.RecordingPlaybackTTL = resolveDuration(*recordingPlaybackTTL, "BITRIVER_LIVE_RECORDING_PLAYBACK_TTL", 0)
+	handler.KeyLeakSourceLimit = resolveInt(*keyLeakSourceLimit, "BITRIVER_LIVE_KEY_LEAK_SOURCE_LIMIT")
+	handler.KeyLeakWindow = resolveDuration(*keyLeakWindow, "BITRIVER_LIVE_KEY_LEAK_WINDOW", 0)
+	handler.SessionPeakInterval = resolveDuration(*sessionPeakInterval, "BITRIVER_LIVE_SESSION_PEAK_INTERVAL", 0)
+	handler.StatsRollupInterval = statsEvery
+	handler.LogLevels = logLevels
 
 	metricsAccessCfg := server.MetricsAccessConfig{
 		Token:           firstNonEmpty(*metricsToken, os.Getenv("BITRIVER_LIVE_METRICS_TOKEN")),
 		AllowedNetworks: splitAndTrim(firstNonEmpty(*metricsAllowNetworks, os.Getenv("BITRIVER_LIVE_METRICS_ALLOW_NETWORKS"))),
 	}
 
-	tlsCfg := server.TLSConfig{
-		CertFile: tlsCertPath,
-		KeyFile:  tlsKeyPath,
-	}
-
 	srv, err := server.New(handler, server.Config{
 		Addr:                    listenAddr,
 		TLS:                     tlsCfg,
@@ -479,6 +838,10 @@ func main() {
 		SessionCookieSecureMode: sessionCookieSecureMode,
 		SessionCookieCrossSite:  sessionCookieCrossSiteValue,
 		SRSHookToken:            ingestConfig.SRSToken,
+		ProvisioningToken:       firstNonEmpty(*provisioningToken, os.Getenv("BITRIVER_LIVE_PROVISIONING_TOKEN")),
+		IngestEventsToken:       ingestEventsTokenValue,
+		Maintenance:             maintenanceCfg,
+		ReadCache:               readCacheCfg,
 	})
 	if err != nil {
 		logger.Error("failed to initialise server", "error", err)
@@ -535,6 +898,22 @@ func main() {
 		}
 	}
 
+	if err := jobPool.Shutdown(ctx); err != nil {
+		logger.Warn("failed to stop job pool", "error", err)
+	}
+
+	if err := ingestHealthPoller.Shutdown(ctx); err != nil {
+		logger.Warn("failed to stop ingest health poller", "error", err)
+	}
+
+	if err := mailQueue.Shutdown(ctx); err != nil {
+		logger.Warn("failed to drain mail queue", "error", err)
+	}
+
+	if err := eventBus.Shutdown(ctx); err != nil {
+		logger.Warn("failed to drain event subscribers", "error", err)
+	}
+
 	if closer, ok := store.(interface{ Close(context.Context) error }); ok {
 		if err := closer.Close(ctx); err != nil {
 			logger.Warn("failed to close datastore", "error", err)
@@ -565,6 +944,7 @@ func main() {
 type sessionStoreConfig struct {
 	Driver      string
 	DSN         string
+	Redis       auth.RedisSessionStoreConfig
 	AbsoluteTTL time.Duration
 	IdleTimeout time.Duration
 }
@@ -610,6 +990,17 @@ func newStartupSummary(in startupSummaryInput) startupSummary {
 	if in.SessionConfig.Driver == "postgres" && in.SessionConfig.DSN != "" {
 		session["dsn"] = redactDSN(in.SessionConfig.DSN)
 	}
+	if in.SessionConfig.Driver == "redis" {
+		if in.SessionConfig.Redis.Addr != "" {
+			session["addr"] = in.SessionConfig.Redis.Addr
+		}
+		if len(in.SessionConfig.Redis.Addrs) > 0 {
+			session["addrs"] = in.SessionConfig.Redis.Addrs
+		}
+		if in.SessionConfig.Redis.MasterName != "" {
+			session["master_name"] = in.SessionConfig.Redis.MasterName
+		}
+	}
 	if in.SessionConfig.AbsoluteTTL > 0 {
 		session["absolute_ttl"] = in.SessionConfig.AbsoluteTTL.String()
 	}
@@ -651,6 +1042,9 @@ func newStartupSummary(in startupSummaryInput) startupSummary {
 		if in.ChatConfig.Group != "" {
 			chatQueue["group"] = in.ChatConfig.Group
 		}
+		if in.ChatConfig.DeadLetterStream != "" {
+			chatQueue["dead_letter_stream"] = in.ChatConfig.DeadLetterStream
+		}
 	}
 
 	ingestSummary := map[string]any{
@@ -666,6 +1060,13 @@ func newStartupSummary(in startupSummaryInput) startupSummary {
 		if in.IngestConfig.JobBaseURL != "" {
 			ingestSummary["transcoder_api"] = in.IngestConfig.JobBaseURL
 		}
+		if len(in.IngestConfig.Transcoders) > 0 {
+			names := make([]string, 0, len(in.IngestConfig.Transcoders))
+			for _, backend := range in.IngestConfig.Transcoders {
+				names = append(names, backend.Name)
+			}
+			ingestSummary["transcoder_backends"] = names
+		}
 		if in.IngestConfig.HealthEndpoint != "" {
 			ingestSummary["health_endpoint"] = in.IngestConfig.HealthEndpoint
 		}
@@ -720,11 +1121,11 @@ func resolveSessionStoreConfig(flagDriver, envDriver, storageDriver, storageDSN,
 		}
 	}
 
-	if requirePostgres && driver != "postgres" {
+	if requirePostgres && driver != "postgres" && driver != "redis" {
 		if driver == "" {
-			return sessionStoreConfig{}, fmt.Errorf("production mode requires the postgres session store driver")
+			return sessionStoreConfig{}, fmt.Errorf("production mode requires the postgres or redis session store driver")
 		}
-		return sessionStoreConfig{}, fmt.Errorf("production mode requires the postgres session store driver, got %q", driver)
+		return sessionStoreConfig{}, fmt.Errorf("production mode requires the postgres or redis session store driver, got %q", driver)
 	}
 
 	switch driver {
@@ -738,6 +1139,8 @@ func resolveSessionStoreConfig(flagDriver, envDriver, storageDriver, storageDSN,
 			return sessionStoreConfig{}, fmt.Errorf("postgres session store selected without DSN")
 		}
 		return sessionStoreConfig{Driver: "postgres", DSN: sessionDSN}, nil
+	case "redis":
+		return sessionStoreConfig{Driver: "redis"}, nil
 	default:
 		return sessionStoreConfig{}, fmt.Errorf("unsupported session store driver %q", driver)
 	}
@@ -757,7 +1160,12 @@ func configureChatQueue(driver string, cfg chat.RedisQueueConfig, logger *slog.L
 		}
 		return queue, nil
 	case "", "memory":
-		return chat.NewMemoryQueue(128), nil
+		return chat.NewMemoryQueueWithConfig(chat.MemoryQueueConfig{
+			Buffer:        128,
+			MaxDeliveries: cfg.MaxDeliveries,
+			RetryDelay:    cfg.ClaimIdle,
+			Metrics:       cfg.Metrics,
+		}), nil
 	default:
 		return nil, fmt.Errorf("unsupported chat queue driver %q", driver)
 	}
@@ -922,6 +1330,24 @@ func resolveViewerOrigin(flagValue, envValue string) (*url.URL, error) {
 	return parsed, nil
 }
 
+// resolveLegacyAPISunset parses the date the unversioned /api/ shapes are
+// retired, as a calendar date or an RFC 3339 timestamp. Empty means no date
+// has been announced.
+func resolveLegacyAPISunset(flagValue, envValue string) (time.Time, error) {
+	raw := firstNonEmpty(flagValue, envValue)
+	if raw == "" {
+		return time.Time{}, nil
+	}
+	if parsed, err := time.Parse("2006-01-02", raw); err == nil {
+		return parsed, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, raw)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("parse legacy api sunset %q: expected YYYY-MM-DD or RFC 3339", raw)
+	}
+	return parsed, nil
+}
+
 func firstNonEmpty(values ...string) string {
 	for _, value := range values {
 		trimmed := strings.TrimSpace(value)
@@ -963,6 +1389,39 @@ func resolveFloat(flagValue float64, envKey string) float64 {
 	return 0
 }
 
+// newMailSender builds the SMTP sender from flags and BITRIVER_LIVE_SMTP_*
+// variables, falling back to logging emails when no relay host is set.
+func newMailSender(host string, port int, username, password, from, tlsMode string, logger *slog.Logger) (mail.Sender, error) {
+	host = firstNonEmpty(host, os.Getenv("BITRIVER_LIVE_SMTP_HOST"))
+	if host == "" {
+		logger.Warn("no SMTP relay configured; emails will be logged instead of sent")
+		return mail.LogSender{Logger: logger}, nil
+	}
+	mode, err := mail.ParseTLSMode(firstNonEmpty(tlsMode, os.Getenv("BITRIVER_LIVE_SMTP_TLS")))
+	if err != nil {
+		return nil, err
+	}
+	return mail.NewSMTPSender(mail.SMTPConfig{
+		Host:     host,
+		Port:     resolveInt(port, "BITRIVER_LIVE_SMTP_PORT"),
+		Username: firstNonEmpty(username, os.Getenv("BITRIVER_LIVE_SMTP_USERNAME")),
+		Password: firstNonEmpty(password, os.Getenv("BITRIVER_LIVE_SMTP_PASSWORD")),
+		From:     firstNonEmpty(from, os.Getenv("BITRIVER_LIVE_SMTP_FROM")),
+		TLS:      mode,
+	})
+}
+
+// newUploadScanner builds the malware scanner for uploads from the flag or
+// BITRIVER_LIVE_CLAMD_ADDRESS. Without an address uploads are not scanned.
+func newUploadScanner(address string, timeout time.Duration, logger *slog.Logger) (malware.Scanner, error) {
+	address = firstNonEmpty(address, os.Getenv("BITRIVER_LIVE_CLAMD_ADDRESS"))
+	if address == "" {
+		logger.Info("no ClamAV daemon configured; uploads will not be scanned for malware")
+		return malware.NoopScanner{}, nil
+	}
+	return malware.NewClamdScanner(malware.ClamdConfig{Address: address, Timeout: timeout})
+}
+
 func resolveInt(flagValue int, envKey string) int {
 	if flagValue > 0 {
 		return flagValue
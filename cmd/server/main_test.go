@@ -105,6 +105,23 @@ func TestResolvePostgresDSNPriority(t *testing.T) {
 	}
 }
 
+func TestResolveLegacyAPISunset(t *testing.T) {
+	got, err := resolveLegacyAPISunset("", "2027-01-31")
+	if err != nil || !got.Equal(time.Date(2027, 1, 31, 0, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected env date, got %v (%v)", got, err)
+	}
+	got, err = resolveLegacyAPISunset("2027-03-01T12:00:00Z", "2027-01-31")
+	if err != nil || !got.Equal(time.Date(2027, 3, 1, 12, 0, 0, 0, time.UTC)) {
+		t.Fatalf("expected flag timestamp to win, got %v (%v)", got, err)
+	}
+	if got, err := resolveLegacyAPISunset("", ""); err != nil || !got.IsZero() {
+		t.Fatalf("expected no sunset when unset, got %v (%v)", got, err)
+	}
+	if _, err := resolveLegacyAPISunset("next year", ""); err == nil {
+		t.Fatal("expected an invalid sunset to be rejected")
+	}
+}
+
 func TestResolveSessionStoreConfig(t *testing.T) {
 	t.Parallel()
 
@@ -157,6 +174,21 @@ func TestResolveSessionStoreConfig(t *testing.T) {
 			requirePostgres: true,
 			want:            sessionStoreConfig{Driver: "postgres", DSN: "postgres://main"},
 		},
+		{
+			name:          "ExplicitRedisIgnoresPostgresStorage",
+			flagDriver:    "redis",
+			storageDriver: "postgres",
+			storageDSN:    "postgres://main",
+			want:          sessionStoreConfig{Driver: "redis"},
+		},
+		{
+			name:            "ProductionAcceptsRedis",
+			envDriver:       "redis",
+			storageDriver:   "postgres",
+			storageDSN:      "postgres://main",
+			requirePostgres: true,
+			want:            sessionStoreConfig{Driver: "redis"},
+		},
 		{
 			name:            "ProductionRejectsExplicitMemory",
 			flagDriver:      "memory",
@@ -5,6 +5,8 @@ import (
 	"log/slog"
 	"sync"
 	"time"
+
+	"bitriver-live/internal/observability/health"
 )
 
 type sessionPurger interface {
@@ -30,8 +32,8 @@ func (t timeTicker) Stop() {
 
 type tickerFactory func(time.Duration) purgeTicker
 
-func startSessionPurgeWorker(ctx context.Context, logger *slog.Logger, sessions sessionPurger, interval time.Duration) func() {
-	return startSessionPurgeWorkerWithTicker(ctx, logger, sessions, interval, func(d time.Duration) purgeTicker {
+func startSessionPurgeWorker(ctx context.Context, logger *slog.Logger, sessions sessionPurger, interval time.Duration, component *health.Component) func() {
+	return startSessionPurgeWorkerWithTicker(ctx, logger, sessions, interval, component, func(d time.Duration) purgeTicker {
 		return timeTicker{ticker: time.NewTicker(d)}
 	})
 }
@@ -41,6 +43,7 @@ func startSessionPurgeWorkerWithTicker(
 	logger *slog.Logger,
 	sessions sessionPurger,
 	interval time.Duration,
+	component *health.Component,
 	newTicker tickerFactory,
 ) func() {
 	if sessions == nil || interval <= 0 {
@@ -49,6 +52,7 @@ func startSessionPurgeWorkerWithTicker(
 	workerCtx, cancel := context.WithCancel(ctx)
 	ticker := newTicker(interval)
 	done := make(chan struct{})
+	component.SetHealthy()
 	go func() {
 		defer func() {
 			ticker.Stop()
@@ -59,9 +63,14 @@ func startSessionPurgeWorkerWithTicker(
 			case <-workerCtx.Done():
 				return
 			case <-ticker.C():
-				if err := sessions.PurgeExpired(); err != nil && logger != nil {
-					logger.Error("failed to purge expired sessions", "error", err)
+				if err := sessions.PurgeExpired(); err != nil {
+					component.SetDegraded(err)
+					if logger != nil {
+						logger.Error("failed to purge expired sessions", "error", err)
+					}
+					continue
 				}
+				component.SetHealthy()
 			}
 		}
 	}()
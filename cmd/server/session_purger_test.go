@@ -2,10 +2,13 @@ package main
 
 import (
 	"context"
+	"errors"
 	"io"
 	"log/slog"
 	"testing"
 	"time"
+
+	"bitriver-live/internal/observability/health"
 )
 
 type fakeSessionManager struct {
@@ -65,7 +68,7 @@ func TestStartSessionPurgeWorker(t *testing.T) {
 	sessions := newFakeSessionManager()
 	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
 
-	stop := startSessionPurgeWorkerWithTicker(ctx, logger, sessions, time.Minute, func(time.Duration) purgeTicker {
+	stop := startSessionPurgeWorkerWithTicker(ctx, logger, sessions, time.Minute, nil, func(time.Duration) purgeTicker {
 		return ticker
 	})
 
@@ -85,3 +88,27 @@ func TestStartSessionPurgeWorker(t *testing.T) {
 		t.Fatal("expected ticker to stop after context cancellation")
 	}
 }
+
+func TestSessionPurgeWorkerReportsHealth(t *testing.T) {
+	ticker := newManualTicker()
+	sessions := newFakeSessionManager()
+	sessions.err = errors.New("session store unavailable")
+	registry := health.NewRegistry(health.Config{})
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	stop := startSessionPurgeWorkerWithTicker(context.Background(), logger, sessions, time.Minute, registry.RegisterComponent("session-purger"), func(time.Duration) purgeTicker {
+		return ticker
+	})
+	ticker.Tick()
+	select {
+	case <-sessions.calls:
+	case <-time.After(time.Second):
+		t.Fatal("expected purge to be invoked")
+	}
+	stop()
+
+	snapshots := registry.Snapshot()
+	if len(snapshots) != 1 || snapshots[0].Status != health.StatusDegraded || snapshots[0].LastError != "session store unavailable" {
+		t.Fatalf("expected degraded purger with the purge error, got %+v", snapshots)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"context"
 	"crypto/subtle"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
@@ -26,13 +27,21 @@ import (
 const (
 	defaultBind     = ":1985"
 	defaultUpstream = "http://localhost:1985/api/"
+	defaultCacheTTL = time.Second
 )
 
+// cacheHeader reports how a GET was served: HIT from the micro-cache, SHARED
+// from a concurrent identical request's upstream call, or MISS.
+const cacheHeader = "X-SRS-Controller-Cache"
+
 type controller struct {
 	token   string
 	client  *http.Client
 	baseURL *url.URL
 	logger  *slog.Logger
+	// cache holds recent GET responses; nil disables caching.
+	cache   *responseCache
+	metrics *metrics.Recorder
 
 	mu               sync.Mutex
 	lastUpstreamErr  error
@@ -67,6 +76,16 @@ func main() {
 		os.Exit(1)
 	}
 
+	cacheTTL := defaultCacheTTL
+	if raw := strings.TrimSpace(os.Getenv("SRS_CONTROLLER_CACHE_TTL")); raw != "" {
+		parsed, err := time.ParseDuration(raw)
+		if err != nil || parsed < 0 {
+			logger.Error("SRS_CONTROLLER_CACHE_TTL must be a non-negative duration", "value", raw)
+			os.Exit(1)
+		}
+		cacheTTL = parsed
+	}
+
 	ctrl := &controller{
 		token: token,
 		client: &http.Client{
@@ -74,6 +93,8 @@ func main() {
 		},
 		baseURL: upstream,
 		logger:  logger,
+		cache:   newResponseCache(cacheTTL, time.Now),
+		metrics: registry.Recorder,
 	}
 
 	mux := http.NewServeMux()
@@ -96,7 +117,7 @@ func main() {
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
 
-	logger.Info("srs controller listening", "bind", bind)
+	logger.Info("srs controller listening", "bind", bind, "cache_ttl", cacheTTL.String())
 	if err := serverutil.Run(ctx, serverutil.Config{Server: server, ShutdownTimeout: 10 * time.Second}); err != nil {
 		logger.Error("server error", "error", err)
 		os.Exit(1)
@@ -125,11 +146,62 @@ func (c *controller) proxyRequest(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	req, err := http.NewRequestWithContext(r.Context(), r.Method, target.String(), bytes.NewReader(body))
+	if r.Method == http.MethodGet && c.cache != nil {
+		c.serveCachedGet(w, r, target)
+		return
+	}
+
+	resp, err := c.forward(r.Context(), r, target, body)
 	if err != nil {
-		http.Error(w, "failed to build upstream request", http.StatusInternalServerError)
+		writeProxyError(w, err)
+		return
+	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil && c.logger != nil {
+			c.logger.Warn("close upstream body", "error", err)
+		}
+	}()
+
+	if r.Method != http.MethodHead && c.cache != nil {
+		c.cache.invalidate(r.URL.Path)
+	}
+
+	copyResponseHeaders(w.Header(), resp.Header)
+	w.WriteHeader(resp.StatusCode)
+	if _, err := io.Copy(w, resp.Body); err != nil {
+		if c.logger != nil {
+			c.logger.Warn("stream upstream body", "error", err)
+		}
+	}
+}
+
+// proxyError carries the status and message returned to the caller when a
+// request cannot be proxied.
+type proxyError struct {
+	status  int
+	message string
+}
+
+func (e proxyError) Error() string {
+	return e.message
+}
+
+func writeProxyError(w http.ResponseWriter, err error) {
+	var perr proxyError
+	if errors.As(err, &perr) {
+		http.Error(w, perr.message, perr.status)
 		return
 	}
+	http.Error(w, "upstream request failed", http.StatusBadGateway)
+}
+
+// forward sends the request to SRS and records the upstream outcome for the
+// health check.
+func (c *controller) forward(ctx context.Context, r *http.Request, target *url.URL, body []byte) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, r.Method, target.String(), bytes.NewReader(body))
+	if err != nil {
+		return nil, proxyError{status: http.StatusInternalServerError, message: "failed to build upstream request"}
+	}
 
 	copyHeaders(req.Header, r.Header)
 	req.Header.Del("Authorization")
@@ -140,28 +212,73 @@ func (c *controller) proxyRequest(w http.ResponseWriter, r *http.Request) {
 		if c.logger != nil {
 			c.logger.Error("upstream request failed", "target", target.String(), "error", err)
 		}
-		http.Error(w, "upstream request failed", http.StatusBadGateway)
-		return
+		return nil, proxyError{status: http.StatusBadGateway, message: "upstream request failed"}
 	}
-	defer func() {
-		if err := resp.Body.Close(); err != nil && c.logger != nil {
-			c.logger.Warn("close upstream body", "error", err)
-		}
-	}()
 
 	if resp.StatusCode >= 500 {
 		c.recordUpstreamError(fmt.Errorf("upstream status %d", resp.StatusCode))
 	} else {
 		c.recordSuccess()
 	}
+	return resp, nil
+}
 
-	copyResponseHeaders(w.Header(), resp.Header)
-	w.WriteHeader(resp.StatusCode)
-	if _, err := io.Copy(w, resp.Body); err != nil {
-		if c.logger != nil {
-			c.logger.Warn("stream upstream body", "error", err)
+// serveCachedGet answers a GET from the micro-cache, or fetches it from SRS
+// once on behalf of every concurrent identical request.
+func (c *controller) serveCachedGet(w http.ResponseWriter, r *http.Request, target *url.URL) {
+	key := r.URL.Path + "?" + r.URL.RawQuery
+	if entry, ok := c.cache.get(key); ok {
+		c.observeCache("hit")
+		writeCachedResponse(w, entry, "HIT")
+		return
+	}
+
+	// The upstream call is shared, so it must not be cancelled when the
+	// request that happened to start it goes away.
+	ctx := context.WithoutCancel(r.Context())
+	entry, shared, err := c.cache.do(key, func() (cachedResponse, error) {
+		resp, err := c.forward(ctx, r, target, nil)
+		if err != nil {
+			return cachedResponse{}, err
+		}
+		defer func() {
+			if err := resp.Body.Close(); err != nil && c.logger != nil {
+				c.logger.Warn("close upstream body", "error", err)
+			}
+		}()
+		payload, err := io.ReadAll(resp.Body)
+		if err != nil {
+			if c.logger != nil {
+				c.logger.Warn("read upstream body", "error", err)
+			}
+			return cachedResponse{}, proxyError{status: http.StatusBadGateway, message: "upstream request failed"}
 		}
+		return cachedResponse{status: resp.StatusCode, header: resp.Header.Clone(), body: payload}, nil
+	})
+	if err != nil {
+		writeProxyError(w, err)
+		return
+	}
+	if shared {
+		c.observeCache("shared")
+		writeCachedResponse(w, entry, "SHARED")
+		return
 	}
+	c.observeCache("miss")
+	writeCachedResponse(w, entry, "MISS")
+}
+
+func (c *controller) observeCache(result string) {
+	if c.metrics != nil {
+		c.metrics.ObserveCacheLookup("srs_controller", result)
+	}
+}
+
+func writeCachedResponse(w http.ResponseWriter, entry cachedResponse, result string) {
+	copyResponseHeaders(w.Header(), entry.header)
+	w.Header().Set(cacheHeader, result)
+	w.WriteHeader(entry.status)
+	_, _ = w.Write(entry.body)
 }
 
 func (c *controller) authorized(header string) bool {
@@ -227,6 +344,119 @@ func (c *controller) healthz(w http.ResponseWriter, _ *http.Request) {
 	_, _ = w.Write(buf)
 }
 
+// responseCache is a short-lived cache of successful GET responses keyed by
+// path and query. Concurrent misses for the same key share one upstream call.
+// Any non-GET request invalidates entries on the same resource path or its
+// ancestors and descendants, and bumps a generation so GETs already in flight
+// when the write completed do not store their now-stale result.
+type responseCache struct {
+	ttl time.Duration
+	now func() time.Time
+
+	mu         sync.Mutex
+	generation uint64
+	entries    map[string]cachedResponse
+	inflight   map[string]*inflightResponse
+}
+
+type cachedResponse struct {
+	status    int
+	header    http.Header
+	body      []byte
+	expiresAt time.Time
+}
+
+type inflightResponse struct {
+	done    chan struct{}
+	entry   cachedResponse
+	err     error
+	waiters int
+}
+
+// newResponseCache returns a cache with the given TTL, or nil when ttl is zero
+// so callers can disable caching.
+func newResponseCache(ttl time.Duration, now func() time.Time) *responseCache {
+	if ttl <= 0 {
+		return nil
+	}
+	if now == nil {
+		now = time.Now
+	}
+	return &responseCache{
+		ttl:      ttl,
+		now:      now,
+		entries:  make(map[string]cachedResponse),
+		inflight: make(map[string]*inflightResponse),
+	}
+}
+
+func (c *responseCache) get(key string) (cachedResponse, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[key]
+	if !ok {
+		return cachedResponse{}, false
+	}
+	if !c.now().Before(entry.expiresAt) {
+		delete(c.entries, key)
+		return cachedResponse{}, false
+	}
+	return entry, true
+}
+
+// do runs fetch once for concurrent callers of the same key. shared reports
+// whether the caller reused another caller's fetch. Only 2xx responses are
+// stored.
+func (c *responseCache) do(key string, fetch func() (cachedResponse, error)) (entry cachedResponse, shared bool, err error) {
+	c.mu.Lock()
+	if pending, ok := c.inflight[key]; ok {
+		pending.waiters++
+		c.mu.Unlock()
+		<-pending.done
+		return pending.entry, true, pending.err
+	}
+	pending := &inflightResponse{done: make(chan struct{})}
+	c.inflight[key] = pending
+	generation := c.generation
+	c.mu.Unlock()
+
+	pending.entry, pending.err = fetch()
+
+	c.mu.Lock()
+	delete(c.inflight, key)
+	if pending.err == nil && pending.entry.status >= 200 && pending.entry.status < 300 && generation == c.generation {
+		stored := pending.entry
+		stored.expiresAt = c.now().Add(c.ttl)
+		c.entries[key] = stored
+	}
+	c.mu.Unlock()
+	close(pending.done)
+	return pending.entry, false, pending.err
+}
+
+// invalidate drops cached responses whose path is path itself, one of its
+// ancestors (such as the channel list), or one of its descendants.
+func (c *responseCache) invalidate(path string) {
+	path = strings.TrimSuffix(path, "/")
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.generation++
+	for key := range c.entries {
+		entryPath, _, _ := strings.Cut(key, "?")
+		entryPath = strings.TrimSuffix(entryPath, "/")
+		if pathsOverlap(entryPath, path) {
+			delete(c.entries, key)
+		}
+	}
+}
+
+func pathsOverlap(a, b string) bool {
+	if len(a) > len(b) {
+		a, b = b, a
+	}
+	return a == b || strings.HasPrefix(b, a+"/")
+}
+
 func readBody(rc io.ReadCloser) ([]byte, error) {
 	if rc == nil {
 		return nil, nil
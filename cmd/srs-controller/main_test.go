@@ -5,8 +5,14 @@ import (
 	"net/http"
 	"net/http/httptest"
 	"net/url"
+	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"bitriver-live/internal/observability/metrics"
 )
 
 func TestProxyRequestForwardsToUpstream(t *testing.T) {
@@ -122,3 +128,213 @@ func TestResolveTargetPreservesQuery(t *testing.T) {
 		t.Fatalf("unexpected target: %s", target)
 	}
 }
+
+type cachingUpstream struct {
+	server  *httptest.Server
+	hits    atomic.Int64
+	status  atomic.Int64
+	release chan struct{}
+}
+
+func newCachingUpstream(t *testing.T) *cachingUpstream {
+	t.Helper()
+	u := &cachingUpstream{}
+	u.status.Store(http.StatusOK)
+	u.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := u.hits.Add(1)
+		if u.release != nil && r.Method == http.MethodGet {
+			<-u.release
+		}
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(int(u.status.Load()))
+		_, _ = io.WriteString(w, `{"method":"`+r.Method+`","hit":`+strconv.FormatInt(n, 10)+`}`)
+	}))
+	t.Cleanup(u.server.Close)
+	return u
+}
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func newCachingController(t *testing.T, upstream *cachingUpstream, clock *fakeClock) *controller {
+	t.Helper()
+	base, err := url.Parse(upstream.server.URL + "/api/")
+	if err != nil {
+		t.Fatalf("parse upstream: %v", err)
+	}
+	return &controller{
+		token:   "secret",
+		client:  upstream.server.Client(),
+		baseURL: base,
+		cache:   newResponseCache(time.Second, clock.Now),
+		metrics: metrics.New(),
+	}
+}
+
+func doProxy(ctrl *controller, method, path string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Authorization", "Bearer secret")
+	rr := httptest.NewRecorder()
+	ctrl.proxyRequest(rr, req)
+	return rr
+}
+
+func TestProxyCoalescesConcurrentGets(t *testing.T) {
+	upstream := newCachingUpstream(t)
+	upstream.release = make(chan struct{})
+	ctrl := newCachingController(t, upstream, &fakeClock{now: time.Unix(0, 0)})
+
+	const callers = 10
+	var wg sync.WaitGroup
+	results := make([]*httptest.ResponseRecorder, callers)
+	for i := 0; i < callers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			results[i] = doProxy(ctrl, http.MethodGet, "/v1/channels/abc")
+		}(i)
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for queuedWaiters(ctrl.cache) < callers-1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("only %d callers joined the in-flight request", queuedWaiters(ctrl.cache))
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(upstream.release)
+	wg.Wait()
+
+	if hits := upstream.hits.Load(); hits != 1 {
+		t.Fatalf("expected a single upstream call, got %d", hits)
+	}
+	misses := 0
+	for i, rr := range results {
+		if rr.Code != http.StatusOK {
+			t.Fatalf("caller %d: unexpected status %d", i, rr.Code)
+		}
+		if rr.Body.String() != `{"method":"GET","hit":1}` {
+			t.Fatalf("caller %d: unexpected body %s", i, rr.Body.String())
+		}
+		if rr.Header().Get(cacheHeader) == "MISS" {
+			misses++
+		}
+	}
+	if misses != 1 {
+		t.Fatalf("expected exactly one MISS, got %d", misses)
+	}
+}
+
+func queuedWaiters(c *responseCache) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	total := 0
+	for _, pending := range c.inflight {
+		total += pending.waiters
+	}
+	return total
+}
+
+func TestProxyCachedGetExpiresAfterTTL(t *testing.T) {
+	upstream := newCachingUpstream(t)
+	clock := &fakeClock{now: time.Unix(0, 0)}
+	ctrl := newCachingController(t, upstream, clock)
+
+	first := doProxy(ctrl, http.MethodGet, "/v1/channels/abc?expand=true")
+	if first.Header().Get(cacheHeader) != "MISS" {
+		t.Fatalf("expected MISS, got %q", first.Header().Get(cacheHeader))
+	}
+	clock.Advance(500 * time.Millisecond)
+	second := doProxy(ctrl, http.MethodGet, "/v1/channels/abc?expand=true")
+	if second.Header().Get(cacheHeader) != "HIT" || second.Body.String() != first.Body.String() {
+		t.Fatalf("expected cached response, got %q %s", second.Header().Get(cacheHeader), second.Body.String())
+	}
+	if other := doProxy(ctrl, http.MethodGet, "/v1/channels/abc"); other.Header().Get(cacheHeader) != "MISS" {
+		t.Fatalf("expected different query to miss, got %q", other.Header().Get(cacheHeader))
+	}
+
+	clock.Advance(time.Second)
+	third := doProxy(ctrl, http.MethodGet, "/v1/channels/abc?expand=true")
+	if third.Header().Get(cacheHeader) != "MISS" {
+		t.Fatalf("expected expired entry to miss, got %q", third.Header().Get(cacheHeader))
+	}
+	if hits := upstream.hits.Load(); hits != 3 {
+		t.Fatalf("expected 3 upstream calls, got %d", hits)
+	}
+
+	counts := ctrl.metrics.CacheLookupCounts()
+	if counts[metrics.CacheLookupLabel{Namespace: "srs_controller", Result: "hit"}] != 1 {
+		t.Fatalf("expected one recorded hit, got %v", counts)
+	}
+	if counts[metrics.CacheLookupLabel{Namespace: "srs_controller", Result: "miss"}] != 3 {
+		t.Fatalf("expected three recorded misses, got %v", counts)
+	}
+}
+
+func TestProxyDeleteInvalidatesCachedGets(t *testing.T) {
+	upstream := newCachingUpstream(t)
+	ctrl := newCachingController(t, upstream, &fakeClock{now: time.Unix(0, 0)})
+
+	for _, path := range []string{"/v1/channels", "/v1/channels/abc", "/v1/channels/xyz"} {
+		doProxy(ctrl, http.MethodGet, path)
+	}
+	if rr := doProxy(ctrl, http.MethodDelete, "/v1/channels/abc"); rr.Code != http.StatusOK {
+		t.Fatalf("unexpected delete status %d", rr.Code)
+	}
+
+	expected := map[string]string{
+		"/v1/channels":     "MISS",
+		"/v1/channels/abc": "MISS",
+		"/v1/channels/xyz": "HIT",
+	}
+	for path, want := range expected {
+		if got := doProxy(ctrl, http.MethodGet, path).Header().Get(cacheHeader); got != want {
+			t.Fatalf("GET %s after DELETE: expected %s, got %s", path, want, got)
+		}
+	}
+}
+
+func TestProxyPassesThroughNonGetRequests(t *testing.T) {
+	upstream := newCachingUpstream(t)
+	ctrl := newCachingController(t, upstream, &fakeClock{now: time.Unix(0, 0)})
+
+	for i := 0; i < 2; i++ {
+		rr := doProxy(ctrl, http.MethodPost, "/v1/channels")
+		if rr.Header().Get(cacheHeader) != "" {
+			t.Fatalf("expected POST to bypass the cache, got %q", rr.Header().Get(cacheHeader))
+		}
+	}
+	if hits := upstream.hits.Load(); hits != 2 {
+		t.Fatalf("expected both POSTs to reach upstream, got %d", hits)
+	}
+}
+
+func TestProxyDoesNotCacheErrorResponses(t *testing.T) {
+	upstream := newCachingUpstream(t)
+	upstream.status.Store(http.StatusNotFound)
+	ctrl := newCachingController(t, upstream, &fakeClock{now: time.Unix(0, 0)})
+
+	for i := 0; i < 2; i++ {
+		rr := doProxy(ctrl, http.MethodGet, "/v1/channels/missing")
+		if rr.Code != http.StatusNotFound || rr.Header().Get(cacheHeader) != "MISS" {
+			t.Fatalf("expected uncached 404, got %d %q", rr.Code, rr.Header().Get(cacheHeader))
+		}
+	}
+	if hits := upstream.hits.Load(); hits != 2 {
+		t.Fatalf("expected every error to reach upstream, got %d", hits)
+	}
+}
@@ -0,0 +1,165 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// sessionCookieName is the cookie the login endpoint stores the session
+// token in.
+const sessionCookieName = "bitriver_session"
+
+// apiError is a non-2xx response from the API, carrying the code and
+// message from its error envelope when it has one.
+type apiError struct {
+	Status  int
+	Code    string
+	Message string
+}
+
+func (e *apiError) Error() string {
+	message := e.Message
+	if message == "" {
+		message = http.StatusText(e.Status)
+	}
+	if e.Code != "" {
+		return fmt.Sprintf("%s (HTTP %d, %s)", message, e.Status, e.Code)
+	}
+	return fmt.Sprintf("%s (HTTP %d)", message, e.Status)
+}
+
+// client calls the BitRiver Live REST API with a bearer token, which is
+// either a personal access token or a session token from login.
+type client struct {
+	server string
+	token  string
+	http   *http.Client
+}
+
+func newClient(server, token string, httpClient *http.Client) (*client, error) {
+	parsed, err := url.Parse(strings.TrimSpace(server))
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return nil, fmt.Errorf("server %q must be an http or https URL", server)
+	}
+	if httpClient == nil {
+		httpClient = http.DefaultClient
+	}
+	return &client{server: strings.TrimRight(parsed.String(), "/"), token: token, http: httpClient}, nil
+}
+
+// do sends body as JSON when it is non-nil and decodes a successful
+// response into out when out is non-nil. Any status outside 2xx is returned
+// as an *apiError.
+func (c *client) do(ctx context.Context, method, path string, body, out any) error {
+	_, err := c.send(ctx, method, path, body, out, false)
+	return err
+}
+
+// get is do for reads whose body is meaningful even when the status reports
+// a failure, such as the health endpoints answering 503 while degraded. It
+// returns the status code alongside the decoded body.
+func (c *client) get(ctx context.Context, path string, out any) (int, error) {
+	return c.send(ctx, http.MethodGet, path, nil, out, true)
+}
+
+func (c *client) send(ctx context.Context, method, path string, body, out any, decodeErrors bool) (int, error) {
+	resp, err := c.request(ctx, method, path, body)
+	if err != nil {
+		return 0, err
+	}
+	defer resp.Body.Close()
+
+	payload, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return resp.StatusCode, fmt.Errorf("%s %s: read response: %w", method, path, err)
+	}
+	failed := resp.StatusCode < 200 || resp.StatusCode > 299
+	if failed && !decodeErrors {
+		return resp.StatusCode, newAPIError(resp.StatusCode, payload)
+	}
+	if out != nil && len(bytes.TrimSpace(payload)) > 0 {
+		if err := json.Unmarshal(payload, out); err != nil {
+			if failed {
+				return resp.StatusCode, newAPIError(resp.StatusCode, payload)
+			}
+			return resp.StatusCode, fmt.Errorf("%s %s: decode response: %w", method, path, err)
+		}
+	}
+	return resp.StatusCode, nil
+}
+
+func (c *client) request(ctx context.Context, method, path string, body any) (*http.Response, error) {
+	var reader io.Reader
+	if body != nil {
+		encoded, err := json.Marshal(body)
+		if err != nil {
+			return nil, fmt.Errorf("%s %s: encode request: %w", method, path, err)
+		}
+		reader = bytes.NewReader(encoded)
+	}
+	req, err := http.NewRequestWithContext(ctx, method, c.server+path, reader)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/json")
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	if c.token != "" {
+		req.Header.Set("Authorization", "Bearer "+c.token)
+	}
+	resp, err := c.http.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("%s %s: %w", method, path, err)
+	}
+	return resp, nil
+}
+
+func newAPIError(status int, payload []byte) *apiError {
+	apiErr := &apiError{Status: status}
+	var envelope struct {
+		Error struct {
+			Code    string `json:"code"`
+			Message string `json:"message"`
+		} `json:"error"`
+	}
+	if json.Unmarshal(payload, &envelope) == nil && envelope.Error.Message != "" {
+		apiErr.Code = envelope.Error.Code
+		apiErr.Message = envelope.Error.Message
+	} else if text := strings.TrimSpace(string(payload)); text != "" && len(text) <= 200 {
+		apiErr.Message = text
+	}
+	return apiErr
+}
+
+// login exchanges an email and password for a session token and uses it
+// for later requests.
+func (c *client) login(ctx context.Context, email, password string) error {
+	resp, err := c.request(ctx, http.MethodPost, "/api/auth/login", map[string]string{"email": email, "password": password})
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	payload, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusOK {
+		return newAPIError(resp.StatusCode, payload)
+	}
+	for _, cookie := range resp.Cookies() {
+		if cookie.Name == sessionCookieName && cookie.Value != "" {
+			c.token = cookie.Value
+			return nil
+		}
+	}
+	return fmt.Errorf("login succeeded but no session was issued")
+}
+
+// logout revokes the session login created.
+func (c *client) logout(ctx context.Context) error {
+	return c.do(ctx, http.MethodDelete, "/api/auth/session", nil, nil)
+}
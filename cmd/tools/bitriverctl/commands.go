@@ -0,0 +1,436 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	"bitriver-live/internal/ingest"
+)
+
+// command is a parsed subcommand ready to run against the API.
+type command struct {
+	name      string
+	needsAuth bool
+	run       func(ctx context.Context, api *client, out *output) error
+}
+
+// parseCommand validates args and binds them to a command without touching
+// the network, so malformed invocations fail before signing in.
+func parseCommand(args []string) (command, error) {
+	group, rest := args[0], args[1:]
+	if group == "health" {
+		if len(rest) != 0 {
+			return command{}, usagef("health takes no arguments")
+		}
+		return command{name: "health", run: runHealth}, nil
+	}
+	if len(rest) == 0 {
+		switch group {
+		case "channel", "user", "report":
+			return command{}, usagef("%s requires a subcommand", group)
+		}
+		return command{}, usagef("unknown command %q (expected channel, user, report, or health)", group)
+	}
+	name := group + " " + rest[0]
+	flags := flag.NewFlagSet(name, flag.ContinueOnError)
+	flags.SetOutput(io.Discard)
+
+	switch name {
+	case "channel force-stop":
+		id, err := parseSingleID(flags, rest[1:], "channel")
+		if err != nil {
+			return command{}, err
+		}
+		return command{name: name, needsAuth: true, run: func(ctx context.Context, api *client, out *output) error {
+			return forceStopChannel(ctx, api, out, id)
+		}}, nil
+	case "channel rotate-key":
+		id, err := parseSingleID(flags, rest[1:], "channel")
+		if err != nil {
+			return command{}, err
+		}
+		return command{name: name, needsAuth: true, run: func(ctx context.Context, api *client, out *output) error {
+			return rotateStreamKey(ctx, api, out, id)
+		}}, nil
+	case "user list":
+		query := flags.String("query", "", "")
+		role := flags.String("role", "", "")
+		page := flags.Int("page", 1, "")
+		perPage := flags.Int("per-page", 50, "")
+		positional, err := parseInterspersed(flags, rest[1:])
+		if err != nil {
+			return command{}, usagef("%s: %v", name, err)
+		}
+		if len(positional) != 0 {
+			return command{}, usagef("%s takes no arguments", name)
+		}
+		if *page < 1 || *perPage < 1 {
+			return command{}, usagef("%s: --page and --per-page must be positive", name)
+		}
+		values := url.Values{}
+		values.Set("page", strconv.Itoa(*page))
+		values.Set("perPage", strconv.Itoa(*perPage))
+		if q := strings.TrimSpace(*query); q != "" {
+			values.Set("q", q)
+		}
+		if r := strings.TrimSpace(*role); r != "" {
+			values.Set("role", r)
+		}
+		return command{name: name, needsAuth: true, run: func(ctx context.Context, api *client, out *output) error {
+			return listUsers(ctx, api, out, values)
+		}}, nil
+	case "user disable", "user enable":
+		id, err := parseSingleID(flags, rest[1:], "user")
+		if err != nil {
+			return command{}, err
+		}
+		active := rest[0] == "enable"
+		return command{name: name, needsAuth: true, run: func(ctx context.Context, api *client, out *output) error {
+			return setUserActive(ctx, api, out, id, active)
+		}}, nil
+	case "report list":
+		subjectType := flags.String("subject-type", "", "")
+		category := flags.String("category", "", "")
+		positional, err := parseInterspersed(flags, rest[1:])
+		if err != nil {
+			return command{}, usagef("%s: %v", name, err)
+		}
+		if len(positional) != 0 {
+			return command{}, usagef("%s takes no arguments", name)
+		}
+		values := url.Values{}
+		if value := strings.TrimSpace(*subjectType); value != "" {
+			values.Set("subjectType", value)
+		}
+		if value := strings.TrimSpace(*category); value != "" {
+			values.Set("category", value)
+		}
+		return command{name: name, needsAuth: true, run: func(ctx context.Context, api *client, out *output) error {
+			return listReports(ctx, api, out, values)
+		}}, nil
+	case "report resolve":
+		resolution := flags.String("resolution", "", "")
+		action := flags.String("action", "", "")
+		id, err := parseSingleID(flags, rest[1:], "report")
+		if err != nil {
+			return command{}, err
+		}
+		if strings.TrimSpace(*resolution) == "" {
+			return command{}, usagef("%s requires --resolution", name)
+		}
+		switch *action {
+		case "", "stop_stream", "unpublish_recording":
+		default:
+			return command{}, usagef("%s: --action must be stop_stream or unpublish_recording", name)
+		}
+		req := resolveReportRequest{Resolution: strings.TrimSpace(*resolution), Action: *action}
+		return command{name: name, needsAuth: true, run: func(ctx context.Context, api *client, out *output) error {
+			return resolveReport(ctx, api, out, id, req)
+		}}, nil
+	}
+	switch group {
+	case "channel":
+		return command{}, usagef("unknown channel command %q (expected force-stop or rotate-key)", rest[0])
+	case "user":
+		return command{}, usagef("unknown user command %q (expected list, disable, or enable)", rest[0])
+	case "report":
+		return command{}, usagef("unknown report command %q (expected list or resolve)", rest[0])
+	}
+	return command{}, usagef("unknown command %q (expected channel, user, report, or health)", group)
+}
+
+// parseInterspersed parses flags that may appear before or after the
+// positional arguments and returns the positional arguments.
+func parseInterspersed(flags *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := flags.Parse(args); err != nil {
+			return nil, err
+		}
+		args = flags.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
+
+func parseSingleID(flags *flag.FlagSet, args []string, kind string) (string, error) {
+	positional, err := parseInterspersed(flags, args)
+	if err != nil {
+		return "", usagef("%s: %v", flags.Name(), err)
+	}
+	if len(positional) != 1 || strings.TrimSpace(positional[0]) == "" {
+		return "", usagef("%s requires exactly one %s id", flags.Name(), kind)
+	}
+	return strings.TrimSpace(positional[0]), nil
+}
+
+// output renders results as tables or, with --json, as the API's own JSON.
+type output struct {
+	w    io.Writer
+	json bool
+}
+
+// render prints raw as indented JSON in JSON mode. Otherwise it decodes raw
+// into v and calls table.
+func (o *output) render(raw json.RawMessage, v any, table func(w *tabwriter.Writer)) error {
+	if o.json {
+		return o.printJSON(raw)
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return fmt.Errorf("decode response: %w", err)
+	}
+	w := tabwriter.NewWriter(o.w, 0, 4, 2, ' ', 0)
+	table(w)
+	return w.Flush()
+}
+
+func (o *output) printJSON(v any) error {
+	encoder := json.NewEncoder(o.w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(v)
+}
+
+// The API's response types are unexported, so the fields the tables need
+// are declared here.
+
+type sessionSummary struct {
+	ID             string `json:"id"`
+	ChannelID      string `json:"channelId"`
+	StartedAt      string `json:"startedAt"`
+	EndedAt        string `json:"endedAt"`
+	PeakConcurrent int    `json:"peakConcurrent"`
+}
+
+type channelSummary struct {
+	ID              string `json:"id"`
+	Title           string `json:"title"`
+	StreamKey       string `json:"streamKey"`
+	StreamKeyNotice string `json:"streamKeyNotice"`
+}
+
+type userSummary struct {
+	ID            string   `json:"id"`
+	DisplayName   string   `json:"displayName"`
+	Username      string   `json:"username"`
+	Email         string   `json:"email"`
+	Roles         []string `json:"roles"`
+	CreatedAt     string   `json:"createdAt"`
+	DeactivatedAt string   `json:"deactivatedAt"`
+}
+
+func (u userSummary) status() string {
+	if u.DeactivatedAt != "" {
+		return "deactivated"
+	}
+	return "active"
+}
+
+type userPage struct {
+	Items   []userSummary `json:"items"`
+	Total   int           `json:"total"`
+	Page    int           `json:"page"`
+	PerPage int           `json:"perPage"`
+}
+
+type reportSummary struct {
+	ID           string `json:"id"`
+	ChannelID    string `json:"channelId"`
+	ChannelTitle string `json:"channelTitle"`
+	SubjectType  string `json:"subjectType"`
+	SubjectID    string `json:"subjectId"`
+	Category     string `json:"category"`
+	Reason       string `json:"reason"`
+	FlaggedAt    string `json:"flaggedAt"`
+	Target       *struct {
+		DisplayName string `json:"displayName"`
+	} `json:"target"`
+	Status     string `json:"status"`
+	Resolution string `json:"resolution"`
+}
+
+type resolveReportRequest struct {
+	Resolution string `json:"resolution"`
+	Action     string `json:"action,omitempty"`
+}
+
+type componentSummary struct {
+	Component string `json:"component"`
+	Name      string `json:"name"`
+	Status    string `json:"status"`
+	Error     string `json:"error"`
+	LastError string `json:"lastError"`
+}
+
+type readinessReport struct {
+	Status     string             `json:"status"`
+	Components []componentSummary `json:"components"`
+	Background []componentSummary `json:"background"`
+}
+
+type healthReport struct {
+	Status             string                `json:"status"`
+	Services           []ingest.HealthStatus `json:"services"`
+	ServicesAgeSeconds *int                  `json:"servicesAgeSeconds"`
+}
+
+func forceStopChannel(ctx context.Context, api *client, out *output, channelID string) error {
+	var raw json.RawMessage
+	if err := api.do(ctx, http.MethodPost, "/api/channels/"+url.PathEscape(channelID)+"/stream/stop", struct{}{}, &raw); err != nil {
+		return err
+	}
+	var session sessionSummary
+	return out.render(raw, &session, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "stopped session %s on channel %s (peak %d viewers)\n", session.ID, session.ChannelID, session.PeakConcurrent)
+	})
+}
+
+func rotateStreamKey(ctx context.Context, api *client, out *output, channelID string) error {
+	var raw json.RawMessage
+	if err := api.do(ctx, http.MethodPost, "/api/channels/"+url.PathEscape(channelID)+"/stream/rotate", nil, &raw); err != nil {
+		return err
+	}
+	var channel channelSummary
+	return out.render(raw, &channel, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "rotated stream key for %s (%s)\n", channel.ID, channel.Title)
+		fmt.Fprintf(w, "stream key:\t%s\n", channel.StreamKey)
+		if channel.StreamKeyNotice != "" {
+			fmt.Fprintln(w, channel.StreamKeyNotice)
+		}
+	})
+}
+
+func listUsers(ctx context.Context, api *client, out *output, values url.Values) error {
+	var raw json.RawMessage
+	if err := api.do(ctx, http.MethodGet, "/api/users?"+values.Encode(), nil, &raw); err != nil {
+		return err
+	}
+	var page userPage
+	return out.render(raw, &page, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "ID\tUSERNAME\tNAME\tEMAIL\tROLES\tSTATUS\tCREATED")
+		for _, user := range page.Items {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", user.ID, user.Username, user.DisplayName, user.Email, strings.Join(user.Roles, ","), user.status(), user.CreatedAt)
+		}
+		fmt.Fprintf(w, "page %d, %d of %d users\n", page.Page, len(page.Items), page.Total)
+	})
+}
+
+func setUserActive(ctx context.Context, api *client, out *output, userID string, active bool) error {
+	var raw json.RawMessage
+	if err := api.do(ctx, http.MethodPatch, "/api/users/"+url.PathEscape(userID), map[string]bool{"active": active}, &raw); err != nil {
+		return err
+	}
+	var user userSummary
+	return out.render(raw, &user, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "%s (%s) is now %s\n", user.ID, user.Email, user.status())
+	})
+}
+
+func listReports(ctx context.Context, api *client, out *output, values url.Values) error {
+	path := "/api/moderation/queue"
+	if len(values) > 0 {
+		path += "?" + values.Encode()
+	}
+	var raw json.RawMessage
+	if err := api.do(ctx, http.MethodGet, path, nil, &raw); err != nil {
+		return err
+	}
+	var queue struct {
+		Queue []reportSummary `json:"queue"`
+	}
+	return out.render(raw, &queue, func(w *tabwriter.Writer) {
+		fmt.Fprintln(w, "ID\tCHANNEL\tSUBJECT\tCATEGORY\tTARGET\tREASON\tFLAGGED")
+		for _, report := range queue.Queue {
+			channel := report.ChannelTitle
+			if channel == "" {
+				channel = report.ChannelID
+			}
+			subject := report.SubjectType
+			if report.SubjectID != "" {
+				subject += ":" + report.SubjectID
+			}
+			target := ""
+			if report.Target != nil {
+				target = report.Target.DisplayName
+			}
+			fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%s\n", report.ID, channel, subject, report.Category, target, report.Reason, report.FlaggedAt)
+		}
+	})
+}
+
+func resolveReport(ctx context.Context, api *client, out *output, reportID string, req resolveReportRequest) error {
+	var raw json.RawMessage
+	if err := api.do(ctx, http.MethodPost, "/api/moderation/queue/"+url.PathEscape(reportID), req, &raw); err != nil {
+		return err
+	}
+	var report reportSummary
+	return out.render(raw, &report, func(w *tabwriter.Writer) {
+		fmt.Fprintf(w, "report %s is %s: %s\n", report.ID, report.Status, report.Resolution)
+	})
+}
+
+// runHealth prints /readyz and the ingest checks from /healthz. Both answer
+// 503 with a full report while degraded, so their bodies are shown either
+// way and a degraded platform exits non-zero.
+func runHealth(ctx context.Context, api *client, out *output) error {
+	var readyRaw, healthRaw json.RawMessage
+	readyStatus, err := api.get(ctx, "/readyz", &readyRaw)
+	if err != nil {
+		return err
+	}
+	healthStatus, err := api.get(ctx, "/healthz", &healthRaw)
+	if err != nil {
+		return err
+	}
+	var ready readinessReport
+	var health healthReport
+	for _, decode := range []struct {
+		status int
+		raw    json.RawMessage
+		v      any
+	}{{readyStatus, readyRaw, &ready}, {healthStatus, healthRaw, &health}} {
+		if err := json.Unmarshal(decode.raw, decode.v); err != nil {
+			return &apiError{Status: decode.status, Message: "unexpected health response"}
+		}
+	}
+
+	if out.json {
+		if err := out.printJSON(map[string]json.RawMessage{"ready": readyRaw, "health": healthRaw}); err != nil {
+			return err
+		}
+	} else {
+		w := tabwriter.NewWriter(out.w, 0, 4, 2, ' ', 0)
+		fmt.Fprintf(w, "ready: %s\n", ready.Status)
+		fmt.Fprintf(w, "health: %s\n\n", health.Status)
+		fmt.Fprintln(w, "KIND\tNAME\tSTATUS\tDETAIL")
+		for _, component := range ready.Components {
+			fmt.Fprintf(w, "dependency\t%s\t%s\t%s\n", component.Component, component.Status, component.Error)
+		}
+		for _, component := range ready.Background {
+			fmt.Fprintf(w, "background\t%s\t%s\t%s\n", component.Name, component.Status, component.LastError)
+		}
+		for _, service := range health.Services {
+			fmt.Fprintf(w, "ingest\t%s\t%s\t%s\n", service.Component, service.Status, service.Detail)
+		}
+		if health.ServicesAgeSeconds != nil {
+			fmt.Fprintf(w, "\ningest checked %ds ago\n", *health.ServicesAgeSeconds)
+		}
+		if err := w.Flush(); err != nil {
+			return err
+		}
+	}
+	if ready.Status != "ok" || health.Status != "ok" {
+		return errDegraded
+	}
+	return nil
+}
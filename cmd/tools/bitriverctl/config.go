@@ -0,0 +1,71 @@
+package main
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// configFileName is the file in the home directory that supplies server and
+// token when neither a flag nor an environment variable sets them.
+const configFileName = ".bitriverctl"
+
+// fileConfig holds the settings read from the config file.
+type fileConfig struct {
+	Server string
+	Token  string
+}
+
+func defaultConfigPath() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(home, configFileName)
+}
+
+// loadConfig reads key = value lines from path. Blank lines and lines
+// starting with # are ignored. A missing file yields an empty config.
+func loadConfig(path string) (fileConfig, error) {
+	var cfg fileConfig
+	if path == "" {
+		return cfg, nil
+	}
+	file, err := os.Open(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return cfg, nil
+	}
+	if err != nil {
+		return cfg, fmt.Errorf("read config %s: %w", path, err)
+	}
+	defer file.Close()
+
+	scanner := bufio.NewScanner(file)
+	for line := 1; scanner.Scan(); line++ {
+		text := strings.TrimSpace(scanner.Text())
+		if text == "" || strings.HasPrefix(text, "#") {
+			continue
+		}
+		key, value, ok := strings.Cut(text, "=")
+		if !ok {
+			return cfg, fmt.Errorf("%s:%d: expected key = value", path, line)
+		}
+		value = strings.Trim(strings.TrimSpace(value), `"'`)
+		switch strings.TrimSpace(key) {
+		case "server":
+			cfg.Server = value
+		case "token":
+			cfg.Token = value
+		default:
+			return cfg, fmt.Errorf("%s:%d: unknown key %q (expected server or token)", path, line, strings.TrimSpace(key))
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return cfg, fmt.Errorf("read config %s: %w", path, err)
+	}
+	return cfg, nil
+}
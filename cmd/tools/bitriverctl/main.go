@@ -0,0 +1,159 @@
+// Command bitriverctl runs common administrator tasks against a running
+// BitRiver Live API: force-stopping streams, rotating stream keys, listing
+// and deactivating users, resolving reports, and checking health. It goes
+// through the REST API rather than the datastore, so every action is
+// validated and audited exactly as it is in the control centre.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+)
+
+const usage = `usage: bitriverctl [flags] <command> [args...]
+
+commands:
+  channel force-stop <channel-id>             stop the channel's live stream
+  channel rotate-key <channel-id>             issue a new stream key and print it
+  user list [--query q] [--role r] [--page n] [--per-page n]
+  user disable <user-id>                      deactivate the account and revoke its sessions
+  user enable <user-id>                       reactivate the account
+  report list [--subject-type t] [--category c]
+  report resolve <report-id> --resolution text [--action stop_stream|unpublish_recording]
+  health                                      show readiness and ingest health
+
+Authenticate with an administrator's personal access token (--token,
+BITRIVERCTL_TOKEN, or token in ~/.bitriverctl), or with --email and
+--password (or BITRIVERCTL_PASSWORD) for a session that is revoked on exit.
+
+flags:
+`
+
+const (
+	exitOK      = 0
+	exitFailure = 1
+	exitUsage   = 2
+)
+
+// usageError reports a malformed invocation, which exits with exitUsage
+// rather than exitFailure.
+type usageError struct {
+	message string
+}
+
+func (e usageError) Error() string { return e.message }
+
+func usagef(format string, args ...any) error {
+	return usageError{message: fmt.Sprintf(format, args...)}
+}
+
+// errDegraded is returned by health when any component is not ok, after
+// the report has been printed.
+var errDegraded = errors.New("one or more components are not healthy")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Stdout, os.Stderr, os.Getenv, http.DefaultClient))
+}
+
+// run executes one bitriverctl invocation and returns its exit code.
+func run(args []string, stdout, stderr io.Writer, getenv func(string) string, httpClient *http.Client) int {
+	flags := flag.NewFlagSet("bitriverctl", flag.ContinueOnError)
+	flags.SetOutput(stderr)
+	server := flags.String("server", "", "API base URL, for example https://live.example.com (env BITRIVERCTL_SERVER)")
+	token := flags.String("token", "", "personal access token (env BITRIVERCTL_TOKEN)")
+	email := flags.String("email", "", "administrator email to sign in with instead of a token")
+	password := flags.String("password", "", "password for --email (env BITRIVERCTL_PASSWORD)")
+	jsonOutput := flags.Bool("json", false, "print API responses as JSON instead of tables")
+	configPath := flags.String("config", defaultConfigPath(), "config file supplying server and token")
+	timeout := flags.Duration("timeout", 30*time.Second, "overall timeout for the command")
+	flags.Usage = func() {
+		fmt.Fprint(flags.Output(), usage)
+		flags.PrintDefaults()
+	}
+	if err := flags.Parse(args); err != nil {
+		if errors.Is(err, flag.ErrHelp) {
+			return exitOK
+		}
+		return exitUsage
+	}
+	if flags.NArg() == 0 {
+		flags.Usage()
+		return exitUsage
+	}
+
+	cmd, err := parseCommand(flags.Args())
+	if err != nil {
+		fmt.Fprintf(stderr, "bitriverctl: %v\n", err)
+		return exitUsage
+	}
+
+	cfg, err := loadConfig(*configPath)
+	if err != nil {
+		fmt.Fprintf(stderr, "bitriverctl: %v\n", err)
+		return exitUsage
+	}
+	serverURL := firstNonEmpty(*server, getenv("BITRIVERCTL_SERVER"), cfg.Server)
+	if serverURL == "" {
+		fmt.Fprintln(stderr, "bitriverctl: --server, BITRIVERCTL_SERVER, or server in the config file is required")
+		return exitUsage
+	}
+	apiToken := firstNonEmpty(*token, getenv("BITRIVERCTL_TOKEN"))
+	loginEmail := strings.TrimSpace(*email)
+	if apiToken == "" && loginEmail == "" {
+		apiToken = cfg.Token
+	}
+	loginPassword := firstNonEmpty(*password, getenv("BITRIVERCTL_PASSWORD"))
+	switch {
+	case loginEmail != "" && apiToken != "":
+		fmt.Fprintln(stderr, "bitriverctl: use either a token or --email, not both")
+		return exitUsage
+	case loginEmail != "" && loginPassword == "":
+		fmt.Fprintln(stderr, "bitriverctl: --password or BITRIVERCTL_PASSWORD is required with --email")
+		return exitUsage
+	case cmd.needsAuth && apiToken == "" && loginEmail == "":
+		fmt.Fprintln(stderr, "bitriverctl: a token or --email and --password are required")
+		return exitUsage
+	}
+
+	api, err := newClient(serverURL, apiToken, httpClient)
+	if err != nil {
+		fmt.Fprintf(stderr, "bitriverctl: %v\n", err)
+		return exitUsage
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+	if loginEmail != "" {
+		if err := api.login(ctx, loginEmail, loginPassword); err != nil {
+			fmt.Fprintf(stderr, "bitriverctl: sign in: %v\n", err)
+			return exitFailure
+		}
+		defer func() {
+			if err := api.logout(ctx); err != nil {
+				fmt.Fprintf(stderr, "bitriverctl: sign out: %v\n", err)
+			}
+		}()
+	}
+
+	out := &output{w: stdout, json: *jsonOutput}
+	if err := cmd.run(ctx, api, out); err != nil {
+		fmt.Fprintf(stderr, "bitriverctl: %s: %v\n", cmd.name, err)
+		return exitFailure
+	}
+	return exitOK
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if trimmed := strings.TrimSpace(value); trimmed != "" {
+			return trimmed
+		}
+	}
+	return ""
+}
@@ -0,0 +1,330 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+)
+
+// recordedRequest is what the API double saw.
+type recordedRequest struct {
+	Method string
+	Path   string
+	Query  string
+	Auth   string
+	Body   string
+}
+
+// apiDouble answers with canned responses keyed by "METHOD /path" and
+// records every request.
+type apiDouble struct {
+	mu        sync.Mutex
+	requests  []recordedRequest
+	responses map[string]cannedResponse
+}
+
+type cannedResponse struct {
+	status int
+	body   string
+	cookie *http.Cookie
+}
+
+func newAPIDouble(t *testing.T, responses map[string]cannedResponse) (*apiDouble, string) {
+	t.Helper()
+	double := &apiDouble{responses: responses}
+	server := httptest.NewServer(double)
+	t.Cleanup(server.Close)
+	return double, server.URL
+}
+
+func (d *apiDouble) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, _ := io.ReadAll(r.Body)
+	d.mu.Lock()
+	d.requests = append(d.requests, recordedRequest{Method: r.Method, Path: r.URL.Path, Query: r.URL.RawQuery, Auth: r.Header.Get("Authorization"), Body: string(body)})
+	d.mu.Unlock()
+	resp, ok := d.responses[r.Method+" "+r.URL.Path]
+	if !ok {
+		w.WriteHeader(http.StatusNotFound)
+		_, _ = io.WriteString(w, `{"error":{"code":"not_found","message":"no such route"}}`)
+		return
+	}
+	if resp.cookie != nil {
+		http.SetCookie(w, resp.cookie)
+	}
+	w.Header().Set("Content-Type", "application/json")
+	if resp.status == 0 {
+		resp.status = http.StatusOK
+	}
+	w.WriteHeader(resp.status)
+	_, _ = io.WriteString(w, resp.body)
+}
+
+func (d *apiDouble) recorded() []recordedRequest {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	return append([]recordedRequest(nil), d.requests...)
+}
+
+// runCLI runs bitriverctl with no config file or environment beyond env.
+func runCLI(t *testing.T, env map[string]string, args ...string) (int, string, string) {
+	t.Helper()
+	var stdout, stderr bytes.Buffer
+	args = append([]string{"--config", ""}, args...)
+	code := run(args, &stdout, &stderr, func(key string) string { return env[key] }, http.DefaultClient)
+	return code, stdout.String(), stderr.String()
+}
+
+func TestParseCommandRejectsMalformedInvocations(t *testing.T) {
+	cases := []struct {
+		args []string
+		want string
+	}{
+		{args: []string{"channel"}, want: "channel requires a subcommand"},
+		{args: []string{"channel", "suspend", "ch-1"}, want: `unknown channel command "suspend"`},
+		{args: []string{"channel", "force-stop"}, want: "exactly one channel id"},
+		{args: []string{"channel", "rotate-key", "a", "b"}, want: "exactly one channel id"},
+		{args: []string{"user", "list", "--page", "0"}, want: "must be positive"},
+		{args: []string{"user", "disable"}, want: "exactly one user id"},
+		{args: []string{"report", "resolve", "r-1"}, want: "requires --resolution"},
+		{args: []string{"report", "resolve", "r-1", "--resolution", "ok", "--action", "ban"}, want: "--action must be"},
+		{args: []string{"report", "list", "--bogus"}, want: "flag provided but not defined"},
+		{args: []string{"health", "now"}, want: "health takes no arguments"},
+		{args: []string{"stream"}, want: `unknown command "stream"`},
+	}
+	for _, tc := range cases {
+		_, err := parseCommand(tc.args)
+		if err == nil || !strings.Contains(err.Error(), tc.want) {
+			t.Errorf("parseCommand(%q): expected error containing %q, got %v", tc.args, tc.want, err)
+		}
+		if _, ok := err.(usageError); err != nil && !ok {
+			t.Errorf("parseCommand(%q): expected a usage error, got %T", tc.args, err)
+		}
+	}
+
+	cmd, err := parseCommand([]string{"report", "resolve", "r-1", "--resolution", "handled", "--action", "stop_stream"})
+	if err != nil || cmd.name != "report resolve" || !cmd.needsAuth {
+		t.Fatalf("expected flags after the id to parse, got %+v, %v", cmd, err)
+	}
+}
+
+func TestRunBuildsAPIRequests(t *testing.T) {
+	cases := []struct {
+		name     string
+		args     []string
+		route    string
+		response string
+		want     recordedRequest
+	}{
+		{
+			name:     "force stop",
+			args:     []string{"channel", "force-stop", "ch-1"},
+			route:    "POST /api/channels/ch-1/stream/stop",
+			response: `{"id":"sess-1","channelId":"ch-1","peakConcurrent":12}`,
+			want:     recordedRequest{Method: http.MethodPost, Path: "/api/channels/ch-1/stream/stop", Body: `{}`},
+		},
+		{
+			name:     "rotate key",
+			args:     []string{"channel", "rotate-key", "ch-1"},
+			route:    "POST /api/channels/ch-1/stream/rotate",
+			response: `{"id":"ch-1","title":"Main","streamKey":"new-key"}`,
+			want:     recordedRequest{Method: http.MethodPost, Path: "/api/channels/ch-1/stream/rotate"},
+		},
+		{
+			name:     "list users",
+			args:     []string{"user", "list", "--query", "ada", "--role", "admin", "--per-page", "10"},
+			route:    "GET /api/users",
+			response: `{"items":[],"total":0,"page":1,"perPage":10}`,
+			want:     recordedRequest{Method: http.MethodGet, Path: "/api/users", Query: "page=1&perPage=10&q=ada&role=admin"},
+		},
+		{
+			name:     "disable user",
+			args:     []string{"user", "disable", "user-1"},
+			route:    "PATCH /api/users/user-1",
+			response: `{"id":"user-1","email":"a@example.com","deactivatedAt":"2024-01-01T00:00:00Z"}`,
+			want:     recordedRequest{Method: http.MethodPatch, Path: "/api/users/user-1", Body: `{"active":false}`},
+		},
+		{
+			name:     "list reports",
+			args:     []string{"report", "list", "--category", "spam"},
+			route:    "GET /api/moderation/queue",
+			response: `{"queue":[],"actions":[]}`,
+			want:     recordedRequest{Method: http.MethodGet, Path: "/api/moderation/queue", Query: "category=spam"},
+		},
+		{
+			name:     "resolve report",
+			args:     []string{"report", "resolve", "--resolution", "stream stopped", "r-1", "--action", "stop_stream"},
+			route:    "POST /api/moderation/queue/r-1",
+			response: `{"id":"r-1","status":"resolved","resolution":"stream stopped"}`,
+			want:     recordedRequest{Method: http.MethodPost, Path: "/api/moderation/queue/r-1", Body: `{"resolution":"stream stopped","action":"stop_stream"}`},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			double, server := newAPIDouble(t, map[string]cannedResponse{tc.route: {body: tc.response}})
+			code, _, stderr := runCLI(t, nil, append([]string{"--server", server, "--token", "brl_test"}, tc.args...)...)
+			if code != exitOK {
+				t.Fatalf("expected exit 0, got %d: %s", code, stderr)
+			}
+			requests := double.recorded()
+			if len(requests) != 1 {
+				t.Fatalf("expected one request, got %+v", requests)
+			}
+			tc.want.Auth = "Bearer brl_test"
+			if requests[0] != tc.want {
+				t.Fatalf("expected request %+v, got %+v", tc.want, requests[0])
+			}
+		})
+	}
+}
+
+func TestRunPrintsTablesOrJSON(t *testing.T) {
+	users := `{"items":[{"id":"user-1","username":"ada","displayName":"Ada","email":"ada@example.com","roles":["admin","creator"],"createdAt":"2024-01-01T00:00:00Z","extra":"kept"}],"total":1,"page":1,"perPage":50}`
+	_, server := newAPIDouble(t, map[string]cannedResponse{"GET /api/users": {body: users}})
+
+	code, stdout, stderr := runCLI(t, nil, "--server", server, "--token", "brl_test", "user", "list")
+	if code != exitOK {
+		t.Fatalf("expected exit 0, got %d: %s", code, stderr)
+	}
+	lines := strings.Split(strings.TrimSpace(stdout), "\n")
+	if len(lines) != 3 || !strings.HasPrefix(lines[0], "ID") || !strings.Contains(lines[1], "admin,creator") || !strings.Contains(lines[1], "active") {
+		t.Fatalf("expected a header, one user row, and a footer, got:\n%s", stdout)
+	}
+
+	code, stdout, stderr = runCLI(t, nil, "--server", server, "--token", "brl_test", "--json", "user", "list")
+	if code != exitOK {
+		t.Fatalf("expected exit 0, got %d: %s", code, stderr)
+	}
+	var decoded map[string]any
+	if err := json.Unmarshal([]byte(stdout), &decoded); err != nil {
+		t.Fatalf("expected JSON output, got %q: %v", stdout, err)
+	}
+	items, _ := decoded["items"].([]any)
+	if len(items) != 1 || items[0].(map[string]any)["extra"] != "kept" {
+		t.Fatalf("expected the API response passed through unchanged, got %v", decoded)
+	}
+}
+
+func TestRunExitsNonZeroOnAPIErrors(t *testing.T) {
+	_, server := newAPIDouble(t, map[string]cannedResponse{
+		"POST /api/moderation/queue/r-1": {status: http.StatusForbidden, body: `{"error":{"code":"forbidden","message":"access tokens cannot be used for this request"}}`},
+	})
+	code, stdout, stderr := runCLI(t, nil, "--server", server, "--token", "brl_test", "report", "resolve", "r-1", "--resolution", "ok")
+	if code != exitFailure {
+		t.Fatalf("expected exit %d, got %d", exitFailure, code)
+	}
+	if stdout != "" || !strings.Contains(stderr, "access tokens cannot be used for this request (HTTP 403, forbidden)") {
+		t.Fatalf("expected the API error on stderr only, got stdout %q stderr %q", stdout, stderr)
+	}
+
+	if code, _, _ := runCLI(t, nil, "--server", server, "--token", "brl_test", "channel", "force-stop", "missing"); code != exitFailure {
+		t.Fatalf("expected exit %d for a 404, got %d", exitFailure, code)
+	}
+	if code, _, stderr := runCLI(t, nil, "--server", server, "user", "list"); code != exitUsage || !strings.Contains(stderr, "token") {
+		t.Fatalf("expected a usage error without credentials, got %d: %s", code, stderr)
+	}
+	if code, _, _ := runCLI(t, nil, "user", "list"); code != exitUsage {
+		t.Fatalf("expected a usage error without a server, got %d", code)
+	}
+}
+
+func TestRunSignsInWithCredentialsAndSignsOut(t *testing.T) {
+	double, server := newAPIDouble(t, map[string]cannedResponse{
+		"POST /api/auth/login":     {body: `{"user":{"id":"admin"}}`, cookie: &http.Cookie{Name: sessionCookieName, Value: "session-token"}},
+		"PATCH /api/users/user-1":  {body: `{"id":"user-1","email":"a@example.com"}`},
+		"DELETE /api/auth/session": {status: http.StatusNoContent},
+	})
+	env := map[string]string{"BITRIVERCTL_PASSWORD": "correct horse"}
+	code, stdout, stderr := runCLI(t, env, "--server", server, "--email", "admin@example.com", "user", "enable", "user-1")
+	if code != exitOK {
+		t.Fatalf("expected exit 0, got %d: %s", code, stderr)
+	}
+	if !strings.Contains(stdout, "user-1 (a@example.com) is now active") {
+		t.Fatalf("unexpected output %q", stdout)
+	}
+	requests := double.recorded()
+	if len(requests) != 3 {
+		t.Fatalf("expected login, update, and logout, got %+v", requests)
+	}
+	if requests[0].Path != "/api/auth/login" || requests[0].Body != `{"email":"admin@example.com","password":"correct horse"}` {
+		t.Fatalf("unexpected login request %+v", requests[0])
+	}
+	for _, req := range requests[1:] {
+		if req.Auth != "Bearer session-token" {
+			t.Fatalf("expected the session token on %s %s, got %q", req.Method, req.Path, req.Auth)
+		}
+	}
+	if requests[1].Body != `{"active":true}` || requests[2].Method != http.MethodDelete {
+		t.Fatalf("unexpected requests %+v", requests)
+	}
+
+	if code, _, stderr := runCLI(t, nil, "--server", server, "--email", "admin@example.com", "user", "list"); code != exitUsage || !strings.Contains(stderr, "password") {
+		t.Fatalf("expected a usage error without a password, got %d: %s", code, stderr)
+	}
+}
+
+func TestRunFallsBackToConfigFile(t *testing.T) {
+	double, server := newAPIDouble(t, map[string]cannedResponse{"GET /api/moderation/queue": {body: `{"queue":[]}`}})
+	path := filepath.Join(t.TempDir(), configFileName)
+	config := "# bitriverctl\nserver = " + server + "\ntoken = \"brl_from_file\"\n"
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+
+	var stdout, stderr bytes.Buffer
+	code := run([]string{"--config", path, "report", "list"}, &stdout, &stderr, func(string) string { return "" }, http.DefaultClient)
+	if code != exitOK {
+		t.Fatalf("expected exit 0, got %d: %s", code, stderr.String())
+	}
+	code = run([]string{"--config", path, "report", "list"}, &stdout, &stderr, func(key string) string {
+		if key == "BITRIVERCTL_TOKEN" {
+			return "brl_from_env"
+		}
+		return ""
+	}, http.DefaultClient)
+	if code != exitOK {
+		t.Fatalf("expected exit 0, got %d: %s", code, stderr.String())
+	}
+	requests := double.recorded()
+	if len(requests) != 2 || requests[0].Auth != "Bearer brl_from_file" || requests[1].Auth != "Bearer brl_from_env" {
+		t.Fatalf("expected the file token, then the environment token, got %+v", requests)
+	}
+
+	if err := os.WriteFile(path, []byte("endpoint = x\n"), 0o600); err != nil {
+		t.Fatalf("write config: %v", err)
+	}
+	if code := run([]string{"--config", path, "health"}, &stdout, &stderr, func(string) string { return "" }, http.DefaultClient); code != exitUsage {
+		t.Fatalf("expected a usage error for an unknown config key, got %d", code)
+	}
+}
+
+func TestRunHealthReportsDegradedComponents(t *testing.T) {
+	_, server := newAPIDouble(t, map[string]cannedResponse{
+		"GET /readyz":  {body: `{"status":"ok","components":[{"component":"datastore","status":"ok"}]}`},
+		"GET /healthz": {status: http.StatusOK, body: `{"status":"degraded","components":[],"services":[{"component":"srs","status":"error","detail":"connection refused"}],"servicesAgeSeconds":4}`},
+	})
+	code, stdout, stderr := runCLI(t, nil, "--server", server, "health")
+	if code != exitFailure || !strings.Contains(stderr, "not healthy") {
+		t.Fatalf("expected a degraded exit, got %d: %s", code, stderr)
+	}
+	for _, want := range []string{"ready: ok", "health: degraded", "datastore", "connection refused", "checked 4s ago"} {
+		if !strings.Contains(stdout, want) {
+			t.Fatalf("expected %q in the health report, got:\n%s", want, stdout)
+		}
+	}
+
+	code, stdout, _ = runCLI(t, nil, "--server", server, "--json", "health")
+	var decoded struct {
+		Ready  map[string]any `json:"ready"`
+		Health map[string]any `json:"health"`
+	}
+	if code != exitFailure || json.Unmarshal([]byte(stdout), &decoded) != nil || decoded.Health["status"] != "degraded" {
+		t.Fatalf("expected both reports as JSON, got %d: %s", code, stdout)
+	}
+}
@@ -93,7 +93,10 @@ func closeRepository(repo storage.Repository) {
 
 func bootstrapAdmin(repo storage.Repository, email, displayName, password string) (models.User, bool, error) {
 	normalizedEmail := strings.ToLower(strings.TrimSpace(email))
-	users := repo.ListUsers()
+	users, err := repo.ListUsers()
+	if err != nil {
+		return models.User{}, false, fmt.Errorf("list users: %w", err)
+	}
 	for _, existing := range users {
 		if existing.Email == normalizedEmail {
 			return updateAdmin(repo, existing, displayName, password)
@@ -0,0 +1,183 @@
+// Command chat-dlq lists, inspects, re-enqueues, or discards chat events that
+// the Redis chat queue moved to its dead-letter stream after they exhausted
+// their deliveries.
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"bitriver-live/internal/chat"
+)
+
+const usage = `usage: chat-dlq [flags] <command> [ids...]
+
+commands:
+  list               list dead-lettered events (oldest first)
+  show <id>          print a dead-lettered event with its payload
+  requeue <id>...    append events back onto the chat stream and remove them from the DLQ
+  discard <id>...    delete events from the DLQ
+
+flags:
+`
+
+func main() {
+	addr := flag.String("redis-addr", "", "Redis address (env BITRIVER_LIVE_CHAT_QUEUE_REDIS_ADDR)")
+	addrs := flag.String("redis-addrs", "", "comma separated Redis addresses (env BITRIVER_LIVE_CHAT_QUEUE_REDIS_ADDRS)")
+	username := flag.String("redis-username", "", "Redis username (env BITRIVER_LIVE_CHAT_QUEUE_REDIS_USERNAME)")
+	password := flag.String("redis-password", "", "Redis password (env BITRIVER_LIVE_CHAT_QUEUE_REDIS_PASSWORD)")
+	masterName := flag.String("redis-sentinel-master", "", "Redis sentinel master name (env BITRIVER_LIVE_CHAT_QUEUE_REDIS_SENTINEL_MASTER)")
+	stream := flag.String("stream", "", "chat stream key (env BITRIVER_LIVE_CHAT_QUEUE_REDIS_STREAM, default bitriver:chat)")
+	group := flag.String("group", "", "chat consumer group (env BITRIVER_LIVE_CHAT_QUEUE_REDIS_GROUP, default chat-workers)")
+	deadLetterStream := flag.String("dead-letter-stream", "", "dead-letter stream key (env BITRIVER_LIVE_CHAT_QUEUE_REDIS_DEAD_LETTER_STREAM, default <stream>:dead)")
+	tlsCA := flag.String("redis-tls-ca", "", "path to Redis TLS CA certificate (env BITRIVER_LIVE_CHAT_QUEUE_REDIS_TLS_CA)")
+	tlsCert := flag.String("redis-tls-cert", "", "path to Redis TLS client certificate (env BITRIVER_LIVE_CHAT_QUEUE_REDIS_TLS_CERT)")
+	tlsKey := flag.String("redis-tls-key", "", "path to Redis TLS client key (env BITRIVER_LIVE_CHAT_QUEUE_REDIS_TLS_KEY)")
+	tlsServerName := flag.String("redis-tls-server-name", "", "override Redis TLS server name (env BITRIVER_LIVE_CHAT_QUEUE_REDIS_TLS_SERVER_NAME)")
+	tlsSkipVerify := flag.Bool("redis-tls-skip-verify", false, "skip Redis TLS verification")
+	limit := flag.Int("limit", 50, "maximum events to list (0 lists all)")
+	timeout := flag.Duration("timeout", 10*time.Second, "overall timeout for the command")
+	flag.Usage = func() {
+		fmt.Fprint(flag.CommandLine.Output(), usage)
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	args := flag.Args()
+	if len(args) == 0 {
+		flag.Usage()
+		os.Exit(2)
+	}
+	command, ids := args[0], args[1:]
+	switch command {
+	case "list":
+		if len(ids) != 0 {
+			fatalf("list takes no arguments")
+		}
+	case "show":
+		if len(ids) != 1 {
+			fatalf("show requires exactly one id")
+		}
+	case "requeue", "discard":
+		if len(ids) == 0 {
+			fatalf("%s requires at least one id", command)
+		}
+	default:
+		fatalf("unknown command %q (expected list, show, requeue, or discard)", command)
+	}
+
+	cfg := chat.RedisQueueConfig{
+		Addr:             firstNonEmpty(*addr, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_ADDR")),
+		Addrs:            splitAndTrim(firstNonEmpty(*addrs, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_ADDRS"))),
+		Username:         firstNonEmpty(*username, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_USERNAME")),
+		Password:         firstNonEmpty(*password, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_PASSWORD")),
+		MasterName:       firstNonEmpty(*masterName, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_SENTINEL_MASTER")),
+		Stream:           firstNonEmpty(*stream, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_STREAM")),
+		Group:            firstNonEmpty(*group, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_GROUP")),
+		DeadLetterStream: firstNonEmpty(*deadLetterStream, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_DEAD_LETTER_STREAM")),
+		TLS: chat.RedisTLSConfig{
+			CAFile:             firstNonEmpty(*tlsCA, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_TLS_CA")),
+			CertFile:           firstNonEmpty(*tlsCert, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_TLS_CERT")),
+			KeyFile:            firstNonEmpty(*tlsKey, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_TLS_KEY")),
+			ServerName:         firstNonEmpty(*tlsServerName, os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_TLS_SERVER_NAME")),
+			InsecureSkipVerify: *tlsSkipVerify || strings.EqualFold(os.Getenv("BITRIVER_LIVE_CHAT_QUEUE_REDIS_TLS_SKIP_VERIFY"), "true"),
+		},
+	}
+	if cfg.Addr == "" && len(cfg.Addrs) == 0 {
+		fatalf("--redis-addr or BITRIVER_LIVE_CHAT_QUEUE_REDIS_ADDR is required")
+	}
+
+	queue, err := chat.NewRedisQueue(cfg)
+	if err != nil {
+		fatalf("connect to chat queue: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), *timeout)
+	defer cancel()
+
+	switch command {
+	case "list":
+		letters, err := queue.ListDeadLetters(ctx, *limit)
+		if err != nil {
+			fatalf("list: %v", err)
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "ID\tSOURCE\tTYPE\tATTEMPTS\tFAILED AT\tERROR")
+		for _, letter := range letters {
+			fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", letter.ID, letter.SourceID, letter.Event.Type, letter.Attempts, letter.FailedAt.Format(time.RFC3339), letter.Error)
+		}
+		_ = w.Flush()
+	case "show":
+		letter, err := queue.GetDeadLetter(ctx, ids[0])
+		if err != nil {
+			fatalf("show %s: %v", ids[0], err)
+		}
+		out := map[string]any{
+			"id":       letter.ID,
+			"sourceId": letter.SourceID,
+			"error":    letter.Error,
+			"attempts": letter.Attempts,
+			"failedAt": letter.FailedAt,
+			"payload":  json.RawMessage(letter.Payload),
+		}
+		if !json.Valid(letter.Payload) {
+			out["payload"] = string(letter.Payload)
+		}
+		encoder := json.NewEncoder(os.Stdout)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(out); err != nil {
+			fatalf("show %s: %v", ids[0], err)
+		}
+	case "requeue", "discard":
+		failed := false
+		for _, id := range ids {
+			if command == "requeue" {
+				err = queue.RequeueDeadLetter(ctx, id)
+			} else {
+				err = queue.DiscardDeadLetter(ctx, id)
+			}
+			if err != nil {
+				if errors.Is(err, chat.ErrDeadLetterNotFound) {
+					err = fmt.Errorf("not found")
+				}
+				fmt.Fprintf(os.Stderr, "%s %s: %v\n", command, id, err)
+				failed = true
+				continue
+			}
+			fmt.Printf("%sd %s\n", strings.TrimSuffix(command, "e"), id)
+		}
+		if failed {
+			os.Exit(1)
+		}
+	}
+}
+
+func fatalf(format string, args ...any) {
+	fmt.Fprintf(os.Stderr, format+"\n", args...)
+	os.Exit(1)
+}
+
+func firstNonEmpty(values ...string) string {
+	for _, value := range values {
+		if trimmed := strings.TrimSpace(value); trimmed != "" {
+			return trimmed
+		}
+	}
+	return ""
+}
+
+func splitAndTrim(value string) []string {
+	var out []string
+	for _, part := range strings.Split(value, ",") {
+		if trimmed := strings.TrimSpace(part); trimmed != "" {
+			out = append(out, trimmed)
+		}
+	}
+	return out
+}
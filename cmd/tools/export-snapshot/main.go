@@ -0,0 +1,78 @@
+// Command export-snapshot writes a consistent logical backup of the Postgres
+// datastore as a JSON snapshot that can be re-imported with
+// migrate-json-to-postgres or loaded directly by the JSON datastore.
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"strings"
+
+	"bitriver-live/internal/storage"
+)
+
+func main() {
+	postgresDSN := flag.String("postgres-dsn", "", "Postgres connection string")
+	outPath := flag.String("out", "", "path to write the snapshot to (use - for stdout)")
+	compress := flag.Bool("gzip", false, "gzip-compress the snapshot (implied when --out ends in .gz)")
+	chatBatch := flag.Int("chat-batch-size", storage.DefaultSnapshotChatBatchSize, "number of chat messages to read per query")
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	dsn := strings.TrimSpace(*postgresDSN)
+	if dsn == "" {
+		dsn = strings.TrimSpace(os.Getenv("BITRIVER_LIVE_POSTGRES_DSN"))
+	}
+	if dsn == "" {
+		dsn = strings.TrimSpace(os.Getenv("DATABASE_URL"))
+	}
+	if dsn == "" {
+		logger.Error("postgres DSN required", "hint", "set --postgres-dsn, BITRIVER_LIVE_POSTGRES_DSN, or DATABASE_URL")
+		os.Exit(1)
+	}
+
+	out := strings.TrimSpace(*outPath)
+	if out == "" {
+		logger.Error("output path required", "hint", "set --out to a file path or - for stdout")
+		os.Exit(1)
+	}
+	if *chatBatch <= 0 {
+		logger.Error("chat batch size must be positive", "chatBatchSize", *chatBatch)
+		os.Exit(1)
+	}
+	gzipOutput := *compress || strings.HasSuffix(out, ".gz")
+
+	repo, err := storage.NewPostgresRepository(dsn)
+	if err != nil {
+		logger.Error("failed to open postgres repository", "error", err)
+		os.Exit(1)
+	}
+	defer func() {
+		if closer, ok := repo.(interface{ Close(context.Context) error }); ok {
+			_ = closer.Close(context.Background())
+		}
+	}()
+
+	ctx := context.Background()
+	snapshot, err := storage.ExportSnapshotFromPostgres(ctx, repo, storage.WithSnapshotChatBatchSize(*chatBatch))
+	if err != nil {
+		logger.Error("failed to export snapshot", "error", err)
+		os.Exit(1)
+	}
+	counts := snapshot.Counts()
+
+	if out == "-" {
+		err = storage.WriteSnapshotJSON(os.Stdout, snapshot, gzipOutput)
+	} else {
+		err = storage.SaveSnapshotToJSON(out, snapshot, gzipOutput)
+	}
+	if err != nil {
+		logger.Error("failed to write snapshot", "error", err)
+		os.Exit(1)
+	}
+
+	logger.Info("snapshot exported", "out", out, "gzip", gzipOutput, "users", counts.Users, "channels", counts.Channels, "recordings", counts.Recordings, "chatMessages", counts.ChatMessages)
+}
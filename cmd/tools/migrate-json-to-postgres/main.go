@@ -4,22 +4,57 @@ package main
 import (
 	"context"
 	"flag"
-	"fmt"
 	"log/slog"
 	"os"
 	"strings"
 
 	"bitriver-live/internal/storage"
-	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 func main() {
 	jsonPath := flag.String("json", "data/store.json", "path to the JSON datastore to migrate")
 	postgresDSN := flag.String("postgres-dsn", "", "Postgres connection string")
+	dryRun := flag.Bool("dry-run", false, "check the snapshot against the Postgres schema constraints and report violations without connecting or writing")
+	continueOnError := flag.Bool("continue-on-error", false, "import the remaining rows when Postgres rejects one, listing the rejected rows in --report")
+	reportPath := flag.String("report", "", "path to write a JSON report of violations, import steps, row counts, and rejected rows")
 	flag.Parse()
 
 	logger := slog.New(slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelInfo}))
 
+	report := strings.TrimSpace(*reportPath)
+	if *continueOnError && report == "" {
+		logger.Error("report path required", "hint", "set --report so rows skipped by --continue-on-error are recorded")
+		os.Exit(1)
+	}
+
+	snapshot, err := storage.LoadSnapshotFromJSON(*jsonPath)
+	if err != nil {
+		logger.Error("failed to load JSON snapshot", "error", err)
+		os.Exit(1)
+	}
+	counts := snapshot.Counts()
+	logger.Info("loaded JSON snapshot", "path", *jsonPath, "users", counts.Users, "channels", counts.Channels)
+
+	violations := storage.ValidateSnapshot(snapshot)
+	for _, violation := range violations {
+		logger.Warn("schema constraint violation", "table", violation.Table, "key", violation.Key, "problem", violation.Problem)
+	}
+
+	if *dryRun {
+		if report != "" {
+			if err := storage.SaveSnapshotImportReport(report, &storage.SnapshotImportReport{DryRun: true, Violations: violations}); err != nil {
+				logger.Error("failed to write report", "error", err)
+				os.Exit(1)
+			}
+		}
+		if len(violations) > 0 {
+			logger.Error("dry run found schema constraint violations", "violations", len(violations))
+			os.Exit(1)
+		}
+		logger.Info("dry run found no violations", "users", counts.Users, "channels", counts.Channels, "recordings", counts.Recordings)
+		return
+	}
+
 	dsn := strings.TrimSpace(*postgresDSN)
 	if dsn == "" {
 		dsn = strings.TrimSpace(os.Getenv("BITRIVER_LIVE_POSTGRES_DSN"))
@@ -31,14 +66,9 @@ func main() {
 		logger.Error("postgres DSN required", "hint", "set --postgres-dsn, BITRIVER_LIVE_POSTGRES_DSN, or DATABASE_URL")
 		os.Exit(1)
 	}
-
-	snapshot, err := storage.LoadSnapshotFromJSON(*jsonPath)
-	if err != nil {
-		logger.Error("failed to load JSON snapshot", "error", err)
-		os.Exit(1)
+	if len(violations) > 0 && !*continueOnError {
+		logger.Warn("the import stops at the first row Postgres rejects", "violations", len(violations), "hint", "fix the snapshot or run with --continue-on-error")
 	}
-	counts := snapshot.Counts()
-	logger.Info("loaded JSON snapshot", "path", *jsonPath, "users", counts.Users, "channels", counts.Channels)
 
 	repo, err := storage.NewPostgresRepository(dsn)
 	if err != nil {
@@ -51,63 +81,44 @@ func main() {
 		}
 	}()
 
-	if err := storage.ImportSnapshotToPostgres(context.Background(), repo, snapshot); err != nil {
-		logger.Error("failed to import snapshot", "error", err)
+	ctx := context.Background()
+	before, err := storage.CountSnapshotTables(ctx, repo)
+	if err != nil {
+		logger.Error("failed to count existing rows", "error", err)
 		os.Exit(1)
 	}
 
-	if err := verifyCounts(context.Background(), dsn, counts); err != nil {
-		logger.Error("verification failed", "error", err)
-		os.Exit(1)
+	var opts []storage.SnapshotImportOption
+	if *continueOnError {
+		opts = append(opts, storage.WithSnapshotContinueOnError())
 	}
-
-	logger.Info("migration completed", "users", counts.Users, "channels", counts.Channels, "recordings", counts.Recordings)
-}
-
-func verifyCounts(ctx context.Context, dsn string, counts storage.SnapshotCounts) error {
-	cfg, err := pgxpool.ParseConfig(dsn)
-	if err != nil {
-		return fmt.Errorf("parse verification config: %w", err)
+	result, importErr := storage.ImportSnapshotToPostgres(ctx, repo, snapshot, opts...)
+	if result != nil {
+		result.Violations = violations
+		for _, step := range result.Steps {
+			logger.Info("import step finished", "step", step.Step, "status", step.Status)
+		}
+		if report != "" {
+			if err := storage.SaveSnapshotImportReport(report, result); err != nil {
+				logger.Error("failed to write report", "error", err)
+				os.Exit(1)
+			}
+		}
 	}
-	pool, err := pgxpool.NewWithConfig(ctx, cfg)
-	if err != nil {
-		return fmt.Errorf("open verification connection: %w", err)
+	if importErr != nil {
+		logger.Error("failed to import snapshot", "error", importErr, "hint", "fix the data and run again; finished steps are skipped")
+		os.Exit(1)
 	}
-	defer pool.Close()
 
-	checks := []struct {
-		name     string
-		query    string
-		expected int
-	}{
-		{"users", "SELECT COUNT(*) FROM users", counts.Users},
-		{"profiles", "SELECT COUNT(*) FROM profiles", counts.Profiles},
-		{"channels", "SELECT COUNT(*) FROM channels", counts.Channels},
-		{"follows", "SELECT COUNT(*) FROM follows", counts.Follows},
-		{"stream_sessions", "SELECT COUNT(*) FROM stream_sessions", counts.StreamSessions},
-		{"stream_session_manifests", "SELECT COUNT(*) FROM stream_session_manifests", counts.StreamSessionManifests},
-		{"recordings", "SELECT COUNT(*) FROM recordings", counts.Recordings},
-		{"recording_renditions", "SELECT COUNT(*) FROM recording_renditions", counts.RecordingRenditions},
-		{"recording_thumbnails", "SELECT COUNT(*) FROM recording_thumbnails", counts.RecordingThumbnails},
-		{"uploads", "SELECT COUNT(*) FROM uploads", counts.Uploads},
-		{"clip_exports", "SELECT COUNT(*) FROM clip_exports", counts.ClipExports},
-		{"chat_messages", "SELECT COUNT(*) FROM chat_messages", counts.ChatMessages},
-		{"chat_bans", "SELECT COUNT(*) FROM chat_bans", counts.ChatBans},
-		{"chat_timeouts", "SELECT COUNT(*) FROM chat_timeouts", counts.ChatTimeouts},
-		{"chat_reports", "SELECT COUNT(*) FROM chat_reports", counts.ChatReports},
-		{"tips", "SELECT COUNT(*) FROM tips", counts.Tips},
-		{"subscriptions", "SELECT COUNT(*) FROM subscriptions", counts.Subscriptions},
-		{"oauth_accounts", "SELECT COUNT(*) FROM oauth_accounts", counts.OAuthAccounts},
+	if err := storage.VerifySnapshotImport(ctx, repo, before, result); err != nil {
+		logger.Error("verification failed", "error", err)
+		os.Exit(1)
 	}
 
-	for _, check := range checks {
-		var actual int
-		if err := pool.QueryRow(ctx, check.query).Scan(&actual); err != nil {
-			return fmt.Errorf("query %s: %w", check.name, err)
-		}
-		if actual != check.expected {
-			return fmt.Errorf("mismatch for %s: expected %d, got %d", check.name, check.expected, actual)
-		}
+	if len(result.Failures) > 0 {
+		logger.Error("some rows were not imported", "failed", len(result.Failures), "report", report, "hint", "fix the rows and run again; only unfinished steps are retried")
+		os.Exit(1)
 	}
-	return nil
+
+	logger.Info("migration completed", "users", result.Inserted("users"), "channels", result.Inserted("channels"), "recordings", result.Inserted("recordings"))
 }
@@ -0,0 +1,114 @@
+// Command rotate-encryption-key re-encrypts stored secrets (donation
+// addresses, OAuth account emails and subjects, and restream stream keys)
+// under the primary encryption key, so retired keys can be dropped from the
+// keyring.
+package main
+
+import (
+	"context"
+	"flag"
+	"log/slog"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+
+	"bitriver-live/internal/storage"
+)
+
+func main() {
+	postgresDSN := flag.String("postgres-dsn", "", "Postgres connection string")
+	dataPath := flag.String("data", "", "path to a JSON datastore file (instead of Postgres)")
+	encryptionKeys := flag.String("encryption-keys", "", "comma separated id:key encryption keys, primary first (env BITRIVER_LIVE_ENCRYPTION_KEYS)")
+	secretKey := flag.String("secret-key", "", "server secret key that sealed values before the keyring was enabled (env BITRIVER_LIVE_SECRET_KEY)")
+	batchSize := flag.Int("batch-size", storage.DefaultEncryptionRotationBatchSize, "number of rows to rewrite per transaction")
+	flag.Parse()
+
+	logger := slog.New(slog.NewTextHandler(os.Stderr, &slog.HandlerOptions{Level: slog.LevelInfo}))
+
+	rawKeys := strings.TrimSpace(*encryptionKeys)
+	if rawKeys == "" {
+		rawKeys = strings.TrimSpace(os.Getenv("BITRIVER_LIVE_ENCRYPTION_KEYS"))
+	}
+	if rawKeys == "" {
+		logger.Error("encryption keys required", "hint", "set --encryption-keys or BITRIVER_LIVE_ENCRYPTION_KEYS with the new key first")
+		os.Exit(1)
+	}
+	keys, err := storage.ParseEncryptionKeys(rawKeys)
+	if err != nil {
+		logger.Error("invalid encryption keys", "error", err)
+		os.Exit(1)
+	}
+	options := []storage.Option{storage.WithEncryptionKeys(keys)}
+
+	rawSecret := strings.TrimSpace(*secretKey)
+	if rawSecret == "" {
+		rawSecret = strings.TrimSpace(os.Getenv("BITRIVER_LIVE_SECRET_KEY"))
+	}
+	if rawSecret != "" {
+		key, err := storage.ParseSecretKey(rawSecret)
+		if err != nil {
+			logger.Error("invalid secret key", "error", err)
+			os.Exit(1)
+		}
+		options = append(options, storage.WithSecretKey(key))
+	}
+
+	if *batchSize <= 0 {
+		logger.Error("batch size must be positive", "batchSize", *batchSize)
+		os.Exit(1)
+	}
+
+	path := strings.TrimSpace(*dataPath)
+	dsn := strings.TrimSpace(*postgresDSN)
+	if dsn == "" && path == "" {
+		dsn = strings.TrimSpace(os.Getenv("BITRIVER_LIVE_POSTGRES_DSN"))
+	}
+	if dsn == "" && path == "" {
+		dsn = strings.TrimSpace(os.Getenv("DATABASE_URL"))
+	}
+	if dsn != "" && path != "" {
+		logger.Error("choose one datastore", "hint", "set either --postgres-dsn or --data, not both")
+		os.Exit(1)
+	}
+	if dsn == "" && path == "" {
+		logger.Error("datastore required", "hint", "set --postgres-dsn, BITRIVER_LIVE_POSTGRES_DSN, DATABASE_URL, or --data")
+		os.Exit(1)
+	}
+
+	var repo storage.Repository
+	if path != "" {
+		if _, err := os.Stat(path); err != nil {
+			logger.Error("failed to read datastore file", "path", path, "error", err)
+			os.Exit(1)
+		}
+		repo, err = storage.NewStorage(path, options...)
+	} else {
+		repo, err = storage.NewPostgresRepository(dsn, options...)
+	}
+	if err != nil {
+		logger.Error("failed to open datastore", "error", err)
+		os.Exit(1)
+	}
+	defer func() {
+		if closer, ok := repo.(interface{ Close(context.Context) error }); ok {
+			_ = closer.Close(context.Background())
+		}
+	}()
+
+	rotator, ok := repo.(storage.EncryptionRotator)
+	if !ok {
+		logger.Error("datastore does not support key rotation")
+		os.Exit(1)
+	}
+
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	rotation, err := rotator.RotateEncryptedFields(ctx, *batchSize)
+	if err != nil {
+		logger.Error("failed to rotate encrypted fields", "error", err, "donationAddresses", rotation.DonationAddresses, "oauthSubjects", rotation.OAuthSubjects, "oauthEmails", rotation.OAuthEmails, "restreamKeys", rotation.RestreamKeys)
+		os.Exit(1)
+	}
+
+	logger.Info("encrypted fields rotated", "primaryKey", keys[0].ID, "donationAddresses", rotation.DonationAddresses, "oauthSubjects", rotation.OAuthSubjects, "oauthEmails", rotation.OAuthEmails, "restreamKeys", rotation.RestreamKeys)
+}
@@ -0,0 +1,244 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// hlsSegmentSeconds is the target duration ffmpeg cuts live segments at.
+	hlsSegmentSeconds = 4
+	// hlsPlaylistSegments is how many segments the live playlists list.
+	hlsPlaylistSegments = 6
+	defaultClipBuffer   = 60 * time.Second
+	defaultClipDuration = 30 * time.Second
+	clipMuxTimeout      = 30 * time.Second
+)
+
+// clipMuxer joins segments, oldest first, into a single MP4 at dest.
+type clipMuxer func(ctx context.Context, segments []string, dest string) error
+
+// ffmpegClipMux remuxes the segments through ffmpeg's concat protocol without
+// re-encoding, so a clip costs a file copy rather than a transcode.
+func ffmpegClipMux(ctx context.Context, segments []string, dest string) error {
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-hide_banner",
+		"-loglevel", "error",
+		"-y",
+		"-i", "concat:"+strings.Join(segments, "|"),
+		"-c", "copy",
+		"-bsf:a", "aac_adtstoasc",
+		"-movflags", "+faststart",
+		"-f", "mp4",
+		dest,
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("ffmpeg clip mux: %w: %s", err, msg)
+		}
+		return fmt.Errorf("ffmpeg clip mux: %w", err)
+	}
+	return nil
+}
+
+// loadClipBuffer reads how much recent live output each job keeps on disk
+// for clipping. Zero disables live clips and keeps only the segments the
+// playlists reference.
+func loadClipBuffer() (time.Duration, error) {
+	raw := envOrDefault("BITRIVER_TRANSCODER_CLIP_BUFFER", "")
+	if raw == "" {
+		return defaultClipBuffer, nil
+	}
+	value, err := time.ParseDuration(raw)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid BITRIVER_TRANSCODER_CLIP_BUFFER %q", raw)
+	}
+	return value, nil
+}
+
+// clipSegmentCount is the number of segments that cover duration.
+func clipSegmentCount(duration time.Duration) int {
+	if duration <= 0 {
+		return 0
+	}
+	segment := time.Duration(hlsSegmentSeconds) * time.Second
+	return int((duration + segment - 1) / segment)
+}
+
+// retainSegments tells ffmpeg to keep extra segments on disk beyond the ones
+// the playlist lists before deleting them, which is what backs the clip
+// buffer. The option goes before the output path, the last argument.
+func (p *transcodePlan) retainSegments(extra int) {
+	if p == nil || extra <= 0 || len(p.args) == 0 {
+		return
+	}
+	last := len(p.args) - 1
+	args := append([]string{}, p.args[:last]...)
+	args = append(args, "-hls_delete_threshold", strconv.Itoa(extra), p.args[last])
+	p.args = args
+}
+
+// applyClipBuffer sizes the live plan's segment retention to the configured
+// clip buffer.
+func (s *server) applyClipBuffer(plan *transcodePlan) {
+	plan.retainSegments(clipSegmentCount(s.clipBuffer))
+}
+
+// bufferedSegments lists the finished segments in a rendition directory,
+// oldest first. Segments newer than the last one the playlist references are
+// still being written and are skipped; without a playlist every segment is
+// returned.
+func bufferedSegments(dir string) ([]string, error) {
+	matches, err := filepath.Glob(filepath.Join(dir, "segment_*.ts"))
+	if err != nil {
+		return nil, err
+	}
+	sort.Strings(matches)
+	newest := lastPlaylistSegment(filepath.Join(dir, "index.m3u8"))
+	if newest == "" {
+		return matches, nil
+	}
+	for idx, match := range matches {
+		if filepath.Base(match) == newest {
+			return matches[:idx+1], nil
+		}
+	}
+	return matches, nil
+}
+
+// lastPlaylistSegment returns the file name of the last segment a media
+// playlist lists, or "" when the playlist cannot be read.
+func lastPlaylistSegment(playlist string) string {
+	file, err := os.Open(playlist)
+	if err != nil {
+		return ""
+	}
+	defer file.Close()
+	var last string
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		last = filepath.Base(filepath.FromSlash(line))
+	}
+	return last
+}
+
+// selectClipSegments picks the newest segments that cover duration.
+func selectClipSegments(segments []string, duration time.Duration) []string {
+	count := clipSegmentCount(duration)
+	if count > len(segments) {
+		count = len(segments)
+	}
+	return segments[len(segments)-count:]
+}
+
+type clipRequest struct {
+	// Duration is the clip length in seconds; zero takes the default.
+	Duration int `json:"duration"`
+}
+
+type clipResponse struct {
+	ClipID          string `json:"clipId"`
+	PlaybackURL     string `json:"playbackUrl"`
+	DurationSeconds int    `json:"durationSeconds"`
+}
+
+// handleJobClip cuts the last duration seconds of a running live job into an
+// MP4 in the public mirror. The clip comes from the tallest rendition, like
+// preview frames, and is shorter than requested when the job has not yet
+// buffered that much.
+func (s *server) handleJobClip(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.authorize(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	if s.clipBuffer <= 0 || s.muxClip == nil {
+		http.Error(w, "live clips are disabled", http.StatusNotFound)
+		return
+	}
+
+	var req clipRequest
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid payload", http.StatusBadRequest)
+			return
+		}
+	}
+	duration := time.Duration(req.Duration) * time.Second
+	if req.Duration == 0 {
+		duration = min(defaultClipDuration, s.clipBuffer)
+	}
+	if duration <= 0 || duration > s.clipBuffer {
+		http.Error(w, fmt.Sprintf("duration must be between 1 and %d seconds", int(s.clipBuffer/time.Second)), http.StatusBadRequest)
+		return
+	}
+
+	s.mu.RLock()
+	meta, ok := s.jobs[id]
+	stopped := ok && meta.StoppedAt != nil
+	s.mu.RUnlock()
+	if !ok || stopped {
+		http.NotFound(w, r)
+		return
+	}
+	source := frameSource(meta.Renditions)
+	if source == "" {
+		http.Error(w, "no buffered segments", http.StatusConflict)
+		return
+	}
+	segments, err := bufferedSegments(filepath.Dir(source))
+	if err != nil || len(segments) == 0 {
+		http.Error(w, "no buffered segments", http.StatusConflict)
+		return
+	}
+	segments = selectClipSegments(segments, duration)
+
+	clipID := newID("clip")
+	clipDir := filepath.Join(s.publicRoot, "clips")
+	if err := os.MkdirAll(clipDir, 0o755); err != nil {
+		http.Error(w, "unable to prepare clip", http.StatusInternalServerError)
+		return
+	}
+	dest := filepath.Join(clipDir, clipID+".mp4")
+	tmp := filepath.Join(clipDir, "."+clipID+".mp4")
+	defer os.Remove(tmp)
+	ctx, cancel := context.WithTimeout(r.Context(), clipMuxTimeout)
+	err = s.muxClip(ctx, segments, tmp)
+	cancel()
+	if err == nil {
+		err = os.Rename(tmp, dest)
+	}
+	if err != nil {
+		if jobLogger := s.jobLogger(id, meta); jobLogger != nil {
+			jobLogger.Error("create live clip", "error", err)
+		}
+		http.Error(w, "failed to create clip", http.StatusInternalServerError)
+		return
+	}
+
+	s.writeJSON(w, http.StatusCreated, clipResponse{
+		ClipID:          clipID,
+		PlaybackURL:     joinURL(s.publicBase, "clips", clipID+".mp4"),
+		DurationSeconds: len(segments) * hlsSegmentSeconds,
+	})
+}
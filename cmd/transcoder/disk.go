@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	"bitriver-live/internal/observability/metrics"
+)
+
+const (
+	defaultDiskFloorMB       = 1024
+	defaultDiskWarnMB        = 5120
+	defaultDiskCheckInterval = 30 * time.Second
+)
+
+// errDiskStatsUnsupported is reported by statDisk on platforms where free
+// space cannot be read. Admission then lets jobs through.
+var errDiskStatsUnsupported = errors.New("disk statistics unsupported on this platform")
+
+// diskUsage is what statDisk reports for a path: the bytes available to
+// unprivileged writers and the device the path lives on.
+type diskUsage struct {
+	Free   uint64
+	Device uint64
+}
+
+// diskStatFunc reads disk usage for a path. Tests substitute a fake.
+type diskStatFunc func(path string) (diskUsage, error)
+
+// diskLimits configures disk-space admission and monitoring. Jobs and
+// uploads are refused with 507 once a volume drops below Floor; below Warn
+// the monitor logs and reclaims space. A zero Interval disables the monitor
+// but not admission.
+type diskLimits struct {
+	Floor    uint64
+	Warn     uint64
+	Interval time.Duration
+}
+
+// diskVolume is a directory whose filesystem the transcoder writes to.
+type diskVolume struct {
+	Name string
+	Path string
+}
+
+// insufficientStorageError names the volume that fell below the floor.
+type insufficientStorageError struct {
+	Volume string
+	Free   uint64
+	Floor  uint64
+}
+
+func (e *insufficientStorageError) Error() string {
+	return fmt.Sprintf("transcoder out of storage: %s volume has %d bytes free, below the %d byte floor", e.Volume, e.Free, e.Floor)
+}
+
+func loadDiskLimits() (diskLimits, error) {
+	limits := diskLimits{Interval: defaultDiskCheckInterval}
+	floorMB, err := envMegabytes("BITRIVER_TRANSCODER_DISK_FLOOR_MB", defaultDiskFloorMB)
+	if err != nil {
+		return diskLimits{}, err
+	}
+	warnMB, err := envMegabytes("BITRIVER_TRANSCODER_DISK_WARN_MB", defaultDiskWarnMB)
+	if err != nil {
+		return diskLimits{}, err
+	}
+	limits.Floor = floorMB << 20
+	limits.Warn = warnMB << 20
+	if limits.Warn < limits.Floor {
+		limits.Warn = limits.Floor
+	}
+	if raw := envOrDefault("BITRIVER_TRANSCODER_DISK_CHECK_INTERVAL", ""); raw != "" {
+		value, err := time.ParseDuration(raw)
+		if err != nil || value < 0 {
+			return diskLimits{}, fmt.Errorf("invalid BITRIVER_TRANSCODER_DISK_CHECK_INTERVAL %q", raw)
+		}
+		limits.Interval = value
+	}
+	return limits, nil
+}
+
+func envMegabytes(key string, fallback uint64) (uint64, error) {
+	raw := envOrDefault(key, "")
+	if raw == "" {
+		return fallback, nil
+	}
+	value, err := strconv.ParseUint(raw, 10, 64)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s %q", key, raw)
+	}
+	return value, nil
+}
+
+// diskVolumes lists the output root and, when it sits on a different
+// filesystem, the public mirror. Volumes that cannot be read are returned
+// as errors alongside the ones that could.
+func (s *server) diskVolumes() (map[string]diskUsage, error) {
+	stat := s.statDisk
+	if stat == nil {
+		stat = statDisk
+	}
+	usage := make(map[string]diskUsage, 2)
+	var errs []error
+	var outputDevice *uint64
+	for _, volume := range []diskVolume{{Name: "output", Path: s.outputRoot}, {Name: "public", Path: s.publicRoot}} {
+		if volume.Path == "" {
+			continue
+		}
+		current, err := stat(volume.Path)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("stat %s volume: %w", volume.Name, err))
+			continue
+		}
+		if volume.Name == "output" {
+			device := current.Device
+			outputDevice = &device
+		} else if outputDevice != nil && *outputDevice == current.Device {
+			continue
+		}
+		usage[volume.Name] = current
+	}
+	return usage, errors.Join(errs...)
+}
+
+// checkDiskFloor reports an *insufficientStorageError when a volume has
+// less free space than the floor. Volumes that cannot be read are logged
+// and otherwise ignored, so a statfs failure never blocks jobs.
+func (s *server) checkDiskFloor() error {
+	if s.disk.Floor == 0 {
+		return nil
+	}
+	usage, err := s.diskVolumes()
+	if err != nil && s.logger != nil {
+		s.logger.Warn("check free disk space", "error", err)
+	}
+	for _, name := range []string{"output", "public"} {
+		current, ok := usage[name]
+		if ok && current.Free < s.disk.Floor {
+			return &insufficientStorageError{Volume: name, Free: current.Free, Floor: s.disk.Floor}
+		}
+	}
+	return nil
+}
+
+// admitDisk answers 507 Insufficient Storage and returns false when a new
+// job of kind would start below the disk floor.
+func (s *server) admitDisk(w http.ResponseWriter, kind string) bool {
+	err := s.checkDiskFloor()
+	if err == nil {
+		return true
+	}
+	if s.logger != nil {
+		s.logger.Warn("rejecting job while disk is below floor", "kind", kind, "error", err)
+	}
+	http.Error(w, err.Error(), http.StatusInsufficientStorage)
+	metrics.TranscoderJobFailed(kind)
+	return false
+}
+
+// sampleDisk records free space per volume and reclaims space when a volume
+// is below the warning threshold. Crossing the threshold in either
+// direction is logged once.
+func (s *server) sampleDisk() {
+	usage, err := s.diskVolumes()
+	if err != nil && s.logger != nil {
+		s.logger.Warn("sample free disk space", "error", err)
+	}
+	low := false
+	for name, current := range usage {
+		metrics.SetTranscoderDiskFree(name, current.Free)
+		if current.Free < s.disk.Warn {
+			low = true
+			if !s.diskLow && s.logger != nil {
+				s.logger.Warn("free disk space below warning threshold", "volume", name, "free_bytes", current.Free, "warn_bytes", s.disk.Warn, "floor_bytes", s.disk.Floor)
+			}
+		}
+	}
+	if !low {
+		if s.diskLow && s.logger != nil {
+			s.logger.Info("free disk space recovered", "warn_bytes", s.disk.Warn)
+		}
+		s.diskLow = false
+		return
+	}
+	s.diskLow = true
+	reclaim := s.reclaimSpace
+	if reclaim == nil {
+		return
+	}
+	removed, err := reclaim()
+	if err != nil && s.logger != nil {
+		s.logger.Warn("reclaim disk space", "error", err)
+	}
+	if removed > 0 && s.logger != nil {
+		s.logger.Info("reclaimed disk space", "stopped_jobs", removed)
+	}
+}
+
+// runDiskMonitor samples disk space every s.disk.Interval until ctx ends.
+func (s *server) runDiskMonitor(ctx context.Context) {
+	if s.disk.Interval <= 0 {
+		return
+	}
+	s.sampleDisk()
+	ticker := time.NewTicker(s.disk.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			s.sampleDisk()
+		}
+	}
+}
+
+// reclaimStoppedJobs deletes the segments and previews left behind by live
+// jobs that have stopped, keeping their metadata. Stopped jobs are never
+// restarted and their mirrors are already gone, so the files only take up
+// space. It returns how many job directories were cleared.
+func (s *server) reclaimStoppedJobs() (int, error) {
+	root := filepath.Join(s.outputRoot, "live")
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		return 0, err
+	}
+	removed := 0
+	var errs []error
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		id := entry.Name()
+		s.mu.RLock()
+		_, running := s.processes[id]
+		s.mu.RUnlock()
+		if running {
+			continue
+		}
+		dir := filepath.Join(root, id)
+		payload, err := os.ReadFile(filepath.Join(dir, "metadata.json"))
+		if err != nil {
+			continue
+		}
+		var meta job
+		if err := json.Unmarshal(payload, &meta); err != nil || meta.StoppedAt == nil {
+			continue
+		}
+		files, err := os.ReadDir(dir)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		cleared := false
+		for _, file := range files {
+			if file.Name() == "metadata.json" {
+				continue
+			}
+			if err := os.RemoveAll(filepath.Join(dir, file.Name())); err != nil {
+				errs = append(errs, err)
+				continue
+			}
+			cleared = true
+		}
+		if cleared {
+			removed++
+		}
+	}
+	return removed, errors.Join(errs...)
+}
+
+// outputSize sums the sizes of the regular files under dir, which is what a
+// job's output costs the channel it belongs to. A missing dir is empty.
+func outputSize(dir string) (int64, error) {
+	if dir == "" {
+		return 0, nil
+	}
+	var total int64
+	err := filepath.WalkDir(dir, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			if errors.Is(err, fs.ErrNotExist) {
+				return nil
+			}
+			return err
+		}
+		total += info.Size()
+		return nil
+	})
+	return total, err
+}
@@ -0,0 +1,7 @@
+//go:build !linux && !darwin && !freebsd
+
+package main
+
+func statDisk(path string) (diskUsage, error) {
+	return diskUsage{}, errDiskStatsUnsupported
+}
@@ -0,0 +1,26 @@
+//go:build linux || darwin || freebsd
+
+package main
+
+import (
+	"os"
+	"syscall"
+)
+
+// statDisk reads free space with statfs and the device from stat, so
+// callers can tell whether two directories share a filesystem.
+func statDisk(path string) (diskUsage, error) {
+	var fs syscall.Statfs_t
+	if err := syscall.Statfs(path, &fs); err != nil {
+		return diskUsage{}, err
+	}
+	usage := diskUsage{Free: uint64(fs.Bavail) * uint64(fs.Bsize)}
+	info, err := os.Stat(path)
+	if err != nil {
+		return diskUsage{}, err
+	}
+	if st, ok := info.Sys().(*syscall.Stat_t); ok {
+		usage.Device = uint64(st.Dev)
+	}
+	return usage, nil
+}
@@ -0,0 +1,266 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/observability/metrics"
+)
+
+// fakeDisk reports fixed free space and devices per path.
+type fakeDisk struct {
+	free    map[string]uint64
+	devices map[string]uint64
+	err     error
+}
+
+func (f *fakeDisk) stat(path string) (diskUsage, error) {
+	if f.err != nil {
+		return diskUsage{}, f.err
+	}
+	return diskUsage{Free: f.free[path], Device: f.devices[path]}, nil
+}
+
+func newDiskTestServer(t *testing.T) *server {
+	t.Helper()
+	tempDir := t.TempDir()
+	t.Setenv("BITRIVER_TRANSCODER_PUBLIC_BASE_URL", "https://cdn.example.com/hls")
+	t.Setenv("BITRIVER_TRANSCODER_PUBLIC_DIR", filepath.Join(tempDir, "public"))
+	srv, err := newServer(testToken, tempDir, newTestLogger(), newTestRegistry())
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	srv.prober = nil
+	srv.launchProcess = func(jobID string, plan *transcodePlan, onExit func(error)) (*processState, error) {
+		return &processState{cancel: func() {}, done: make(chan struct{})}, nil
+	}
+	srv.disk = diskLimits{Floor: 100 << 20, Warn: 500 << 20}
+	return srv
+}
+
+func TestLoadDiskLimits(t *testing.T) {
+	limits, err := loadDiskLimits()
+	if err != nil {
+		t.Fatalf("load defaults: %v", err)
+	}
+	if limits.Floor != defaultDiskFloorMB<<20 || limits.Warn != defaultDiskWarnMB<<20 || limits.Interval != defaultDiskCheckInterval {
+		t.Fatalf("unexpected defaults: %+v", limits)
+	}
+
+	t.Setenv("BITRIVER_TRANSCODER_DISK_FLOOR_MB", "2048")
+	t.Setenv("BITRIVER_TRANSCODER_DISK_WARN_MB", "100")
+	t.Setenv("BITRIVER_TRANSCODER_DISK_CHECK_INTERVAL", "0")
+	limits, err = loadDiskLimits()
+	if err != nil {
+		t.Fatalf("load overrides: %v", err)
+	}
+	if limits.Floor != 2048<<20 || limits.Warn != limits.Floor || limits.Interval != 0 {
+		t.Fatalf("expected warn raised to the floor and monitor disabled, got %+v", limits)
+	}
+
+	t.Setenv("BITRIVER_TRANSCODER_DISK_FLOOR_MB", "lots")
+	if _, err := loadDiskLimits(); err == nil {
+		t.Fatal("expected invalid floor to be rejected")
+	}
+}
+
+func TestHandlersRefuseJobsBelowDiskFloor(t *testing.T) {
+	metrics.Default().Reset()
+	t.Cleanup(metrics.Default().Reset)
+
+	srv := newDiskTestServer(t)
+	disk := &fakeDisk{
+		free:    map[string]uint64{srv.outputRoot: 50 << 20, srv.publicRoot: 50 << 20},
+		devices: map[string]uint64{srv.outputRoot: 1, srv.publicRoot: 1},
+	}
+	srv.statDisk = disk.stat
+
+	cases := []struct {
+		kind    string
+		path    string
+		handler http.HandlerFunc
+		body    map[string]any
+	}{
+		{kind: "live", path: "/v1/jobs", handler: srv.handleJobs, body: map[string]any{
+			"channelId": "channel-1", "sessionId": "session-1", "originUrl": "https://cdn/source.m3u8",
+			"renditions": []map[string]any{{"name": "720p", "bitrate": 2000}},
+		}},
+		{kind: "upload", path: "/v1/uploads", handler: srv.handleUploads, body: map[string]any{
+			"channelId": "channel-1", "uploadId": "upload-1", "sourceUrl": "https://cdn/source.mp4", "filename": "source.mp4",
+			"renditions": []map[string]any{{"name": "720p", "bitrate": 2000}},
+		}},
+	}
+	send := func(path string, handler http.HandlerFunc, body map[string]any) *httptest.ResponseRecorder {
+		payload, err := json.Marshal(body)
+		if err != nil {
+			t.Fatalf("marshal request: %v", err)
+		}
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(payload))
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		res := httptest.NewRecorder()
+		handler(res, req)
+		return res
+	}
+
+	for _, tc := range cases {
+		res := send(tc.path, tc.handler, tc.body)
+		if res.Code != http.StatusInsufficientStorage {
+			t.Fatalf("%s: expected 507, got %d: %s", tc.kind, res.Code, res.Body.String())
+		}
+		if !strings.Contains(res.Body.String(), "output volume") {
+			t.Fatalf("%s: expected the volume named in %q", tc.kind, res.Body.String())
+		}
+	}
+	events, active := metrics.Default().TranscoderJobCounts()
+	for _, kind := range []string{"live", "upload"} {
+		if events[metrics.TranscoderJobLabel{Kind: kind, Status: "fail"}] != 1 {
+			t.Fatalf("expected one %s failure, got %d", kind, events[metrics.TranscoderJobLabel{Kind: kind, Status: "fail"}])
+		}
+	}
+	if active != 0 {
+		t.Fatalf("expected no active jobs, got %d", active)
+	}
+
+	// A separate public volume is checked on its own.
+	disk.free[srv.outputRoot] = 1 << 30
+	disk.devices[srv.publicRoot] = 2
+	if res := send(cases[0].path, cases[0].handler, cases[0].body); res.Code != http.StatusInsufficientStorage || !strings.Contains(res.Body.String(), "public volume") {
+		t.Fatalf("expected 507 for the public volume, got %d: %s", res.Code, res.Body.String())
+	}
+
+	// Statfs failures must not block jobs.
+	disk.err = errors.New("statfs unavailable")
+	if res := send(cases[0].path, cases[0].handler, cases[0].body); res.Code != http.StatusCreated {
+		t.Fatalf("expected jobs admitted when disk stats fail, got %d: %s", res.Code, res.Body.String())
+	}
+
+	disk.err = nil
+	disk.free[srv.publicRoot] = 1 << 30
+	if res := send(cases[0].path, cases[0].handler, cases[0].body); res.Code != http.StatusCreated {
+		t.Fatalf("expected job admitted above the floor, got %d: %s", res.Code, res.Body.String())
+	}
+}
+
+func TestSampleDiskReclaimsBelowWarning(t *testing.T) {
+	metrics.Default().Reset()
+	t.Cleanup(metrics.Default().Reset)
+
+	srv := newDiskTestServer(t)
+	disk := &fakeDisk{
+		free:    map[string]uint64{srv.outputRoot: 1 << 30, srv.publicRoot: 1 << 30},
+		devices: map[string]uint64{srv.outputRoot: 1, srv.publicRoot: 1},
+	}
+	srv.statDisk = disk.stat
+	reclaims := 0
+	srv.reclaimSpace = func() (int, error) {
+		reclaims++
+		return 1, nil
+	}
+
+	srv.sampleDisk()
+	if reclaims != 0 || srv.diskLow {
+		t.Fatalf("expected no cleanup above the warning threshold, got %d", reclaims)
+	}
+	free := metrics.Default().TranscoderDiskFree()
+	if free["output"] != 1<<30 {
+		t.Fatalf("expected output gauge of %d, got %d", uint64(1<<30), free["output"])
+	}
+	if _, ok := free["public"]; ok {
+		t.Fatal("expected a public volume on the output device to be reported once")
+	}
+
+	disk.free[srv.outputRoot] = 200 << 20
+	srv.sampleDisk()
+	srv.sampleDisk()
+	if reclaims != 2 || !srv.diskLow {
+		t.Fatalf("expected cleanup on every sample below the warning threshold, got %d", reclaims)
+	}
+	if got := metrics.Default().TranscoderDiskFree()["output"]; got != 200<<20 {
+		t.Fatalf("expected output gauge of %d, got %d", uint64(200<<20), got)
+	}
+
+	disk.free[srv.outputRoot] = 1 << 30
+	srv.sampleDisk()
+	if reclaims != 2 || srv.diskLow {
+		t.Fatalf("expected cleanup to stop once space recovers, got %d", reclaims)
+	}
+}
+
+func TestReclaimStoppedJobsKeepsMetadata(t *testing.T) {
+	srv := newDiskTestServer(t)
+	stoppedAt := time.Now().UTC()
+	writeJob := func(id string, meta job, files ...string) string {
+		dir := filepath.Join(srv.outputRoot, "live", id)
+		if err := os.MkdirAll(filepath.Join(dir, "720p"), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		payload, err := json.Marshal(meta)
+		if err != nil {
+			t.Fatalf("marshal metadata: %v", err)
+		}
+		if err := os.WriteFile(filepath.Join(dir, "metadata.json"), payload, 0o644); err != nil {
+			t.Fatalf("write metadata: %v", err)
+		}
+		for _, name := range files {
+			if err := os.WriteFile(filepath.Join(dir, name), []byte("segment"), 0o644); err != nil {
+				t.Fatalf("write %s: %v", name, err)
+			}
+		}
+		return dir
+	}
+	stopped := writeJob("stopped", job{ID: "stopped", StoppedAt: &stoppedAt}, "index.m3u8", "720p/segment-1.ts")
+	active := writeJob("active", job{ID: "active"}, "index.m3u8")
+	running := writeJob("running", job{ID: "running", StoppedAt: &stoppedAt}, "index.m3u8")
+	srv.processes["running"] = &processState{cancel: func() {}, done: make(chan struct{})}
+
+	removed, err := srv.reclaimStoppedJobs()
+	if err != nil {
+		t.Fatalf("reclaim: %v", err)
+	}
+	if removed != 1 {
+		t.Fatalf("expected one job cleared, got %d", removed)
+	}
+	entries, err := os.ReadDir(stopped)
+	if err != nil {
+		t.Fatalf("read stopped job: %v", err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "metadata.json" {
+		t.Fatalf("expected only metadata.json left, got %v", entries)
+	}
+	for _, dir := range []string{active, running} {
+		if _, err := os.Stat(filepath.Join(dir, "index.m3u8")); err != nil {
+			t.Fatalf("expected %s left alone: %v", dir, err)
+		}
+	}
+}
+
+func TestOutputSizeSumsFiles(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.MkdirAll(filepath.Join(dir, "720p"), 0o755); err != nil {
+		t.Fatalf("mkdir: %v", err)
+	}
+	files := map[string]int{"index.m3u8": 40, filepath.Join("720p", "seg-1.ts"): 1000, filepath.Join("720p", "seg-2.ts"): 500}
+	for name, size := range files {
+		if err := os.WriteFile(filepath.Join(dir, name), make([]byte, size), 0o644); err != nil {
+			t.Fatalf("write %s: %v", name, err)
+		}
+	}
+	size, err := outputSize(dir)
+	if err != nil {
+		t.Fatalf("outputSize: %v", err)
+	}
+	if size != 1540 {
+		t.Fatalf("expected 1540 bytes, got %d", size)
+	}
+	if size, err := outputSize(filepath.Join(dir, "missing")); err != nil || size != 0 {
+		t.Fatalf("expected a missing directory to be empty, got %d, %v", size, err)
+	}
+}
@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	defaultFrameInterval = 30 * time.Second
+	frameCaptureTimeout  = 10 * time.Second
+	previewFrameName     = "preview.jpg"
+)
+
+// errFrameCaptureBusy is returned when a job's previous capture is still
+// running; callers keep serving the frame already on disk.
+var errFrameCaptureBusy = errors.New("frame capture already in progress")
+
+// frameCapturer grabs a single video frame from input and writes it to dest
+// as a JPEG.
+type frameCapturer func(ctx context.Context, input, dest string) error
+
+// ffmpegFrameCapture shells out to ffmpeg to grab the first decodable frame.
+func ffmpegFrameCapture(ctx context.Context, input, dest string) error {
+	cmd := exec.CommandContext(ctx, "ffmpeg",
+		"-hide_banner",
+		"-loglevel", "error",
+		"-y",
+		"-i", input,
+		"-vframes", "1",
+		"-q:v", "3",
+		dest,
+	)
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if msg := strings.TrimSpace(stderr.String()); msg != "" {
+			return fmt.Errorf("ffmpeg frame capture: %w: %s", err, msg)
+		}
+		return fmt.Errorf("ffmpeg frame capture: %w", err)
+	}
+	return nil
+}
+
+// loadFrameInterval reads how often live jobs refresh their preview frame.
+// Zero disables periodic capture; frames are then taken on demand.
+func loadFrameInterval() (time.Duration, error) {
+	raw := envOrDefault("BITRIVER_TRANSCODER_FRAME_INTERVAL", "")
+	if raw == "" {
+		return defaultFrameInterval, nil
+	}
+	value, err := time.ParseDuration(raw)
+	if err != nil || value < 0 {
+		return 0, fmt.Errorf("invalid BITRIVER_TRANSCODER_FRAME_INTERVAL %q", raw)
+	}
+	return value, nil
+}
+
+// frameSource returns the manifest of the tallest rendition, breaking ties by
+// bitrate, so clips use the best available picture.
+func frameSource(renditions []rendition) string {
+	return pickRendition(renditions, func(candidate, current rendition) bool {
+		return candidate.Height > current.Height || (candidate.Height == current.Height && candidate.Bitrate > current.Bitrate)
+	})
+}
+
+// previewSource returns the manifest of the shortest rendition, breaking ties
+// by bitrate, so periodic preview captures decode as little as possible.
+func previewSource(renditions []rendition) string {
+	return pickRendition(renditions, func(candidate, current rendition) bool {
+		return candidate.Height < current.Height || (candidate.Height == current.Height && candidate.Bitrate < current.Bitrate)
+	})
+}
+
+// pickRendition returns the manifest of the rendition that better ranks
+// first, skipping renditions without a manifest.
+func pickRendition(renditions []rendition, better func(candidate, current rendition) bool) string {
+	best := -1
+	for idx, candidate := range renditions {
+		if strings.TrimSpace(candidate.ManifestURL) == "" {
+			continue
+		}
+		if best < 0 || better(candidate, renditions[best]) {
+			best = idx
+		}
+	}
+	if best < 0 {
+		return ""
+	}
+	return filepath.FromSlash(renditions[best].ManifestURL)
+}
+
+func previewFramePath(j *job) string {
+	return filepath.Join(j.OutputPath, previewFrameName)
+}
+
+// captureJobFrame refreshes the job's preview frame. The frame is written to a
+// temporary file first so readers never observe a partial JPEG. Only one
+// capture runs per job at a time; overlapping calls return
+// errFrameCaptureBusy without starting ffmpeg.
+func (s *server) captureJobFrame(ctx context.Context, j *job) error {
+	input := previewSource(j.Renditions)
+	if input == "" || strings.TrimSpace(j.OutputPath) == "" {
+		return errors.New("job has no rendition to capture from")
+	}
+	if !s.beginFrameCapture(j.ID) {
+		return errFrameCaptureBusy
+	}
+	defer s.endFrameCapture(j.ID)
+	tmp := filepath.Join(j.OutputPath, "."+newID("frame")+".jpg")
+	defer os.Remove(tmp)
+	if err := s.captureFrame(ctx, input, tmp); err != nil {
+		return err
+	}
+	info, err := os.Stat(tmp)
+	if err != nil {
+		return fmt.Errorf("stat captured frame: %w", err)
+	}
+	if info.Size() == 0 {
+		return errors.New("ffmpeg produced an empty frame")
+	}
+	if err := os.Rename(tmp, previewFramePath(j)); err != nil {
+		return fmt.Errorf("store captured frame: %w", err)
+	}
+	return nil
+}
+
+func (s *server) beginFrameCapture(jobID string) bool {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, busy := s.frameCaptures[jobID]; busy {
+		return false
+	}
+	if s.frameCaptures == nil {
+		s.frameCaptures = make(map[string]struct{})
+	}
+	s.frameCaptures[jobID] = struct{}{}
+	return true
+}
+
+func (s *server) endFrameCapture(jobID string) {
+	s.mu.Lock()
+	delete(s.frameCaptures, jobID)
+	s.mu.Unlock()
+}
+
+type frameLoop struct {
+	cancel context.CancelFunc
+	done   chan struct{}
+}
+
+// startFrameLoop refreshes the job's preview frame every frameInterval until
+// stopFrameLoop is called. The first frame is captured one interval in so
+// ffmpeg has written segments to read from.
+func (s *server) startFrameLoop(j *job) {
+	if s.frameInterval <= 0 || s.captureFrame == nil || j == nil {
+		return
+	}
+	s.stopFrameLoop(j.ID)
+	ctx, cancel := context.WithCancel(context.Background())
+	loop := &frameLoop{cancel: cancel, done: make(chan struct{})}
+	s.mu.Lock()
+	s.frameLoops[j.ID] = loop
+	s.mu.Unlock()
+
+	jobLogger := s.jobLogger(j.ID, j)
+	go func() {
+		defer close(loop.done)
+		ticker := time.NewTicker(s.frameInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+			captureCtx, cancelCapture := context.WithTimeout(ctx, frameCaptureTimeout)
+			err := s.captureJobFrame(captureCtx, j)
+			cancelCapture()
+			if err != nil && !errors.Is(err, errFrameCaptureBusy) && ctx.Err() == nil && jobLogger != nil {
+				jobLogger.Debug("capture preview frame", "error", err)
+			}
+		}
+	}()
+}
+
+// stopFrameLoop stops the job's frame loop, if any, and waits for it to exit.
+func (s *server) stopFrameLoop(jobID string) {
+	s.mu.Lock()
+	loop := s.frameLoops[jobID]
+	delete(s.frameLoops, jobID)
+	s.mu.Unlock()
+	if loop == nil {
+		return
+	}
+	loop.cancel()
+	<-loop.done
+}
+
+// handleJobFrame serves the latest preview frame of a running live job,
+// capturing one on demand when none is recent enough.
+func (s *server) handleJobFrame(w http.ResponseWriter, r *http.Request, id string) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.authorize(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	s.mu.RLock()
+	meta, ok := s.jobs[id]
+	stopped := ok && meta.StoppedAt != nil
+	s.mu.RUnlock()
+	if !ok || stopped {
+		http.NotFound(w, r)
+		return
+	}
+
+	path := previewFramePath(meta)
+	info, err := os.Stat(path)
+	fresh := err == nil && s.frameInterval > 0 && time.Since(info.ModTime()) < 2*s.frameInterval
+	if !fresh && s.captureFrame != nil {
+		ctx, cancel := context.WithTimeout(r.Context(), frameCaptureTimeout)
+		captureErr := s.captureJobFrame(ctx, meta)
+		cancel()
+		if captureErr != nil && !errors.Is(captureErr, errFrameCaptureBusy) {
+			if jobLogger := s.jobLogger(id, meta); jobLogger != nil {
+				jobLogger.Debug("capture preview frame", "error", captureErr)
+			}
+		}
+	}
+	data, err := os.ReadFile(path)
+	if err != nil || len(data) == 0 {
+		http.Error(w, "frame unavailable", http.StatusNotFound)
+		return
+	}
+	w.Header().Set("Content-Type", "image/jpeg")
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(data)
+}
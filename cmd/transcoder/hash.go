@@ -0,0 +1,89 @@
+package main
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const rejectHashMismatch = "hash_mismatch"
+
+// sourceHasher returns the hex-encoded SHA-256 of the media at source.
+type sourceHasher func(ctx context.Context, source string) (string, error)
+
+// validContentHash reports whether value looks like a hex-encoded SHA-256.
+func validContentHash(value string) bool {
+	if len(value) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
+
+// hashUploadSource streams an upload source over HTTP(S) or from the local
+// filesystem and hashes it.
+func hashUploadSource(ctx context.Context, source string) (string, error) {
+	var body io.ReadCloser
+	parsed, err := url.Parse(source)
+	switch {
+	case err == nil && (parsed.Scheme == "http" || parsed.Scheme == "https"):
+		req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+		if err != nil {
+			return "", err
+		}
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			return "", err
+		}
+		if resp.StatusCode != http.StatusOK {
+			_ = resp.Body.Close()
+			return "", fmt.Errorf("fetch upload source: unexpected status %d", resp.StatusCode)
+		}
+		body = resp.Body
+	case err == nil && parsed.Scheme == "file":
+		body, err = os.Open(parsed.Path)
+		if err != nil {
+			return "", err
+		}
+	default:
+		body, err = os.Open(source)
+		if err != nil {
+			return "", err
+		}
+	}
+	defer body.Close()
+
+	hasher := sha256.New()
+	if _, err := io.Copy(hasher, body); err != nil {
+		return "", fmt.Errorf("read upload source: %w", err)
+	}
+	return hex.EncodeToString(hasher.Sum(nil)), nil
+}
+
+// verifyUploadSource confirms the source still has the content the API
+// received, so a file swapped out between upload and processing is never
+// transcoded under the original upload. A mismatch is a probeRejection.
+func (s *server) verifyUploadSource(ctx context.Context, source, expected string) error {
+	expected = strings.ToLower(strings.TrimSpace(expected))
+	if expected == "" {
+		return nil
+	}
+	hash := s.hashSource
+	if hash == nil {
+		hash = hashUploadSource
+	}
+	actual, err := hash(ctx, source)
+	if err != nil {
+		return err
+	}
+	if actual != expected {
+		return &probeRejection{Reason: rejectHashMismatch, Message: "upload source does not match the file that was uploaded"}
+	}
+	return nil
+}
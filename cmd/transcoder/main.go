@@ -26,6 +26,8 @@ import (
 	"syscall"
 	"time"
 
+	"bitriver-live/internal/ingest"
+	"bitriver-live/internal/models"
 	"bitriver-live/internal/observability/logging"
 	"bitriver-live/internal/observability/metrics"
 	"bitriver-live/internal/serverutil"
@@ -52,6 +54,8 @@ type job struct {
 	Playback   string
 	CreatedAt  time.Time
 	StoppedAt  *time.Time
+	// OutputBytes is the size of OutputPath when the job stopped.
+	OutputBytes int64
 }
 
 type uploadJob struct {
@@ -63,8 +67,13 @@ type uploadJob struct {
 	Renditions  []rendition
 	OutputPath  string
 	Playback    string
+	Media       *mediaProbe
 	CreatedAt   time.Time
 	CompletedAt *time.Time
+	// Failed is set when ffmpeg exited with an error.
+	Failed bool
+	// OutputBytes is the size of OutputPath once the transcode completed.
+	OutputBytes int64
 }
 
 type processState struct {
@@ -85,11 +94,36 @@ type server struct {
 	processes     map[string]*processState
 	store         *metadataStore
 	launchProcess func(string, *transcodePlan, func(error)) (*processState, error)
+	captureFrame  frameCapturer
+	frameInterval time.Duration
+	frameLoops    map[string]*frameLoop
+	frameCaptures map[string]struct{}
+	clipBuffer    time.Duration
+	muxClip       clipMuxer
+	prober        mediaProber
+	probeTimeout  time.Duration
+	hashSource    sourceHasher
+	uploadLimits  uploadLimits
+	publicFiles   *publicFileServer
+	disk          diskLimits
+	statDisk      diskStatFunc
+	reclaimSpace  func() (int, error)
 	logger        *slog.Logger
 	metrics       *metrics.Registry
 
 	healthMu   sync.Mutex
 	components map[string]*componentState
+
+	// restreams are keyed by restream ID and guarded by mu. launchRestream
+	// starts one ffmpeg push; tests substitute a fake.
+	restreams       map[string]*restream
+	launchRestream  func(string, []string, func(error)) (*processState, error)
+	restreamRetries int
+	restreamBackoff time.Duration
+
+	// diskLow records whether the last disk sample was below the warning
+	// threshold. Only the disk monitor touches it.
+	diskLow bool
 }
 
 type componentState struct {
@@ -200,6 +234,9 @@ type jobRequest struct {
 	SessionID  string          `json:"sessionId"`
 	OriginURL  string          `json:"originUrl"`
 	Renditions json.RawMessage `json:"renditions"`
+	// Limits caps the ladder for this job. Controllers that predate the
+	// field omit it, which leaves the ladder unlimited.
+	Limits models.TranscodeLimits `json:"limits"`
 }
 
 type jobResponse struct {
@@ -209,17 +246,34 @@ type jobResponse struct {
 }
 
 type uploadRequest struct {
-	ChannelID  string          `json:"channelId"`
-	UploadID   string          `json:"uploadId"`
-	SourceURL  string          `json:"sourceUrl"`
-	Filename   string          `json:"filename"`
-	Renditions json.RawMessage `json:"renditions"`
+	ChannelID    string          `json:"channelId"`
+	UploadID     string          `json:"uploadId"`
+	SourceURL    string          `json:"sourceUrl"`
+	Filename     string          `json:"filename"`
+	ExpectedHash string          `json:"expectedHash"`
+	Renditions   json.RawMessage `json:"renditions"`
 }
 
 type uploadResponse struct {
 	JobID       string          `json:"jobId"`
 	PlaybackURL string          `json:"playbackUrl"`
 	Renditions  json.RawMessage `json:"renditions"`
+	Media       *mediaProbe     `json:"media,omitempty"`
+}
+
+// jobStopResponse reports how much output a stopped live job left behind so
+// the API can account for the recording's storage.
+type jobStopResponse struct {
+	JobID       string `json:"jobId"`
+	OutputBytes int64  `json:"outputBytes"`
+}
+
+// uploadStatusResponse reports the progress of an upload transcode. Status
+// is "running", "completed", or "failed"; OutputBytes is set once completed.
+type uploadStatusResponse struct {
+	JobID       string `json:"jobId"`
+	Status      string `json:"status"`
+	OutputBytes int64  `json:"outputBytes"`
 }
 
 const (
@@ -254,6 +308,7 @@ func main() {
 	logger.Info("ffmpeg job controller listening", "bind", bind)
 	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
 	defer stop()
+	go srv.runDiskMonitor(ctx)
 
 	if err := serverutil.Run(ctx, serverutil.Config{
 		Server:          httpServer,
@@ -288,6 +343,26 @@ func newServer(token, outputRoot string, logger *slog.Logger, registry *metrics.
 	if mirrorRoot == "" {
 		mirrorRoot = filepath.Join(store.root, "public")
 	}
+	limits, probeTimeout, err := loadUploadLimits()
+	if err != nil {
+		return nil, err
+	}
+	frameInterval, err := loadFrameInterval()
+	if err != nil {
+		return nil, err
+	}
+	clipBuffer, err := loadClipBuffer()
+	if err != nil {
+		return nil, err
+	}
+	disk, err := loadDiskLimits()
+	if err != nil {
+		return nil, err
+	}
+	restreamRetries, restreamBackoff, err := loadRestreamPolicy()
+	if err != nil {
+		return nil, err
+	}
 	absMirror, err := filepath.Abs(mirrorRoot)
 	if err != nil {
 		return nil, fmt.Errorf("resolve public mirror: %w", err)
@@ -295,25 +370,47 @@ func newServer(token, outputRoot string, logger *slog.Logger, registry *metrics.
 	if err := os.MkdirAll(absMirror, 0o755); err != nil {
 		return nil, fmt.Errorf("prepare public mirror: %w", err)
 	}
-	for _, sub := range []string{"live", "uploads"} {
+	for _, sub := range []string{"live", "uploads", "clips"} {
 		if err := os.MkdirAll(filepath.Join(absMirror, sub), 0o755); err != nil {
 			return nil, fmt.Errorf("prepare public mirror: %w", err)
 		}
 	}
+	publicFiles, err := loadPublicFileServer(absMirror)
+	if err != nil {
+		return nil, err
+	}
 	srv := &server{
-		token:      token,
-		outputRoot: store.root,
-		publicBase: publicBase,
-		publicRoot: absMirror,
-		jobs:       jobs,
-		uploads:    uploads,
-		processes:  make(map[string]*processState),
-		store:      store,
-		logger:     logger,
-		metrics:    registry,
-		components: make(map[string]*componentState),
+		token:         token,
+		outputRoot:    store.root,
+		publicBase:    publicBase,
+		publicRoot:    absMirror,
+		jobs:          jobs,
+		uploads:       uploads,
+		processes:     make(map[string]*processState),
+		captureFrame:  ffmpegFrameCapture,
+		frameLoops:    make(map[string]*frameLoop),
+		store:         store,
+		prober:        newFFprobeProber(),
+		probeTimeout:  probeTimeout,
+		hashSource:    hashUploadSource,
+		uploadLimits:  limits,
+		publicFiles:   publicFiles,
+		disk:          disk,
+		statDisk:      statDisk,
+		frameInterval: frameInterval,
+		clipBuffer:    clipBuffer,
+		muxClip:       ffmpegClipMux,
+		logger:        logger,
+		metrics:       registry,
+		components:    make(map[string]*componentState),
+
+		restreams:       make(map[string]*restream),
+		restreamRetries: restreamRetries,
+		restreamBackoff: restreamBackoff,
 	}
 	srv.launchProcess = srv.startFFmpeg
+	srv.launchRestream = srv.startRestreamFFmpeg
+	srv.reclaimSpace = srv.reclaimStoppedJobs
 	srv.updateComponent(componentFFmpeg, nil)
 	srv.updateComponent(componentPublishing, nil)
 	srv.restoreActiveProcesses()
@@ -327,6 +424,12 @@ func (s *server) routes() http.Handler {
 	mux.HandleFunc("/v1/jobs", s.handleJobs)
 	mux.HandleFunc("/v1/jobs/", s.handleJobByID)
 	mux.HandleFunc("/v1/uploads", s.handleUploads)
+	mux.HandleFunc("/v1/uploads/", s.handleUploadByID)
+	mux.HandleFunc("/v1/restreams", s.handleRestreams)
+	mux.HandleFunc("/v1/restreams/", s.handleRestreamByChannel)
+	if s.publicFiles != nil {
+		mux.Handle(publicFilesPrefix, s.publicFiles)
+	}
 
 	handler := http.Handler(mux)
 	if s.metrics != nil {
@@ -356,7 +459,7 @@ func (s *server) restoreActiveProcesses() {
 		if strings.TrimSpace(outputDir) == "" {
 			outputDir = filepath.Join(s.outputRoot, "live", jb.ID)
 		}
-		plan, err := buildTranscodePlan(jb.OriginURL, outputDir, jb.Renditions)
+		plan, err := buildTranscodePlan(jb.OriginURL, outputDir, jb.Renditions, models.TranscodeLimits{})
 		if err != nil {
 			if jobLogger != nil {
 				jobLogger.Error("resume job", "error", err)
@@ -364,6 +467,7 @@ func (s *server) restoreActiveProcesses() {
 			s.updateComponent(componentFFmpeg, err)
 			continue
 		}
+		s.applyClipBuffer(plan)
 		proc, err := s.launchProcess(id, plan, s.makeJobExitHandler(id))
 		if err != nil {
 			if jobLogger != nil {
@@ -379,6 +483,7 @@ func (s *server) restoreActiveProcesses() {
 		jb.OutputPath = plan.outputDir
 		jb.Playback = plan.master
 		s.processes[id] = proc
+		s.startFrameLoop(jb)
 		if err := s.store.SaveJob(jb); err != nil {
 			if jobLogger != nil {
 				jobLogger.Error("persist job", "error", err)
@@ -400,7 +505,7 @@ func (s *server) restoreActiveProcesses() {
 		if strings.TrimSpace(outputDir) == "" {
 			outputDir = filepath.Join(s.outputRoot, "uploads", up.ID)
 		}
-		plan, err := buildTranscodePlan(up.SourceURL, outputDir, up.Renditions)
+		plan, err := buildTranscodePlan(up.SourceURL, outputDir, up.Renditions, models.TranscodeLimits{})
 		if err != nil {
 			if uploadLogger != nil {
 				uploadLogger.Error("resume upload", "error", err)
@@ -503,14 +608,27 @@ func (s *server) handleJobs(w http.ResponseWriter, r *http.Request) {
 		metrics.TranscoderJobFailed("live")
 		return
 	}
+	if !s.admitDisk(w, "live") {
+		return
+	}
 
 	jobID := newID("live")
-	plan, err := buildTranscodePlan(req.OriginURL, filepath.Join(s.outputRoot, "live", jobID), renditions)
+	plan, err := buildTranscodePlan(req.OriginURL, filepath.Join(s.outputRoot, "live", jobID), renditions, req.Limits)
 	if err != nil {
+		var limitErr *ingest.TranscodeLimitError
+		if errors.As(err, &limitErr) {
+			if s.logger != nil {
+				s.logger.Warn("live job exceeds transcode limits", "channel_id", req.ChannelID, "reason", limitErr.Reason, "limit", limitErr.Limit, "value", limitErr.Value)
+			}
+			s.writeJSON(w, http.StatusUnprocessableEntity, limitErr)
+			metrics.TranscoderJobFailed("live")
+			return
+		}
 		http.Error(w, "unable to prepare transcode", http.StatusInternalServerError)
 		metrics.TranscoderJobFailed("live")
 		return
 	}
+	s.applyClipBuffer(plan)
 
 	meta := &job{
 		ID:         jobID,
@@ -558,6 +676,7 @@ func (s *server) handleJobs(w http.ResponseWriter, r *http.Request) {
 
 	metrics.TranscoderJobStarted("live")
 	s.updateComponent(componentFFmpeg, nil)
+	s.startFrameLoop(meta)
 
 	if err := s.publishLive(meta); err != nil {
 		if jobLogger != nil {
@@ -586,6 +705,14 @@ func (s *server) handleJobs(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *server) handleJobByID(w http.ResponseWriter, r *http.Request) {
+	if id, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/v1/jobs/"), "/frame"); ok && id != "" && !strings.Contains(id, "/") {
+		s.handleJobFrame(w, r, id)
+		return
+	}
+	if id, ok := strings.CutSuffix(strings.TrimPrefix(r.URL.Path, "/v1/jobs/"), "/clip"); ok && id != "" && !strings.Contains(id, "/") {
+		s.handleJobClip(w, r, id)
+		return
+	}
 	if r.Method != http.MethodDelete {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 		return
@@ -611,6 +738,7 @@ func (s *server) handleJobByID(w http.ResponseWriter, r *http.Request) {
 	}
 	jobLogger := s.jobLogger(id, meta)
 
+	s.stopFrameLoop(id)
 	if proc != nil {
 		proc.cancel()
 		select {
@@ -624,6 +752,13 @@ func (s *server) handleJobByID(w http.ResponseWriter, r *http.Request) {
 
 	now := time.Now().UTC()
 	meta.StoppedAt = &now
+	if size, err := outputSize(meta.OutputPath); err != nil {
+		if jobLogger != nil {
+			jobLogger.Warn("measure job output", "error", err)
+		}
+	} else {
+		meta.OutputBytes = size
+	}
 	if err := s.store.SaveJob(meta); err != nil {
 		if jobLogger != nil {
 			jobLogger.Error("persist stopped job", "error", err)
@@ -640,7 +775,7 @@ func (s *server) handleJobByID(w http.ResponseWriter, r *http.Request) {
 	delete(s.processes, id)
 	s.mu.Unlock()
 
-	w.WriteHeader(http.StatusNoContent)
+	s.writeJSON(w, http.StatusOK, jobStopResponse{JobID: id, OutputBytes: meta.OutputBytes})
 }
 
 func (s *server) handleUploads(w http.ResponseWriter, r *http.Request) {
@@ -670,9 +805,53 @@ func (s *server) handleUploads(w http.ResponseWriter, r *http.Request) {
 		metrics.TranscoderJobFailed("upload")
 		return
 	}
+	expectedHash := strings.ToLower(strings.TrimSpace(req.ExpectedHash))
+	if expectedHash != "" && !validContentHash(expectedHash) {
+		http.Error(w, "expectedHash must be a hex-encoded SHA-256", http.StatusBadRequest)
+		metrics.TranscoderJobFailed("upload")
+		return
+	}
+	if !s.admitDisk(w, "upload") {
+		return
+	}
+
+	if err := s.verifyUploadSource(r.Context(), req.SourceURL, expectedHash); err != nil {
+		var rejection *probeRejection
+		if errors.As(err, &rejection) {
+			s.writeJSON(w, http.StatusUnprocessableEntity, rejection)
+			metrics.TranscoderJobFailed("upload")
+			return
+		}
+		if s.logger != nil {
+			s.logger.Warn("hash upload source", "upload_id", req.UploadID, "error", err)
+		}
+		http.Error(w, "unable to read upload source", http.StatusServiceUnavailable)
+		metrics.TranscoderJobFailed("upload")
+		return
+	}
+
+	probe, err := s.probeUpload(r.Context(), req.SourceURL)
+	if err != nil {
+		var rejection *probeRejection
+		if errors.As(err, &rejection) {
+			s.writeJSON(w, http.StatusUnprocessableEntity, rejection)
+			metrics.TranscoderJobFailed("upload")
+			return
+		}
+		if s.logger != nil {
+			s.logger.Warn("probe upload source", "upload_id", req.UploadID, "error", err)
+		}
+		http.Error(w, "unable to probe upload source", http.StatusServiceUnavailable)
+		metrics.TranscoderJobFailed("upload")
+		return
+	}
+
+	if probe != nil {
+		renditions = fitUploadLadder(renditions, *probe)
+	}
 
 	jobID := newID("upload")
-	plan, err := buildTranscodePlan(req.SourceURL, filepath.Join(s.outputRoot, "uploads", jobID), renditions)
+	plan, err := buildTranscodePlan(req.SourceURL, filepath.Join(s.outputRoot, "uploads", jobID), renditions, models.TranscodeLimits{})
 	if err != nil {
 		http.Error(w, "unable to prepare transcode", http.StatusInternalServerError)
 		metrics.TranscoderJobFailed("upload")
@@ -688,6 +867,7 @@ func (s *server) handleUploads(w http.ResponseWriter, r *http.Request) {
 		Renditions: cloneRenditions(plan.renditions),
 		OutputPath: plan.outputDir,
 		Playback:   plan.master,
+		Media:      probe,
 		CreatedAt:  time.Now().UTC(),
 	}
 
@@ -746,10 +926,47 @@ func (s *server) handleUploads(w http.ResponseWriter, r *http.Request) {
 		JobID:       jobID,
 		PlaybackURL: playback,
 		Renditions:  encodeRenditions(publicRenditions),
+		Media:       probe,
 	}
 	s.writeJSON(w, http.StatusAccepted, resp)
 }
 
+// handleUploadByID reports whether an upload transcode has finished and, once
+// it has, the size of its output.
+func (s *server) handleUploadByID(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.authorize(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	id := strings.TrimPrefix(r.URL.Path, "/v1/uploads/")
+	if id == "" || strings.Contains(id, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	s.mu.RLock()
+	meta, ok := s.uploads[id]
+	var resp uploadStatusResponse
+	if ok {
+		resp = uploadStatusResponse{JobID: id, Status: "running", OutputBytes: meta.OutputBytes}
+		switch {
+		case meta.Failed:
+			resp.Status = "failed"
+		case meta.CompletedAt != nil:
+			resp.Status = "completed"
+		}
+	}
+	s.mu.RUnlock()
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	s.writeJSON(w, http.StatusOK, resp)
+}
+
 func (s *server) makeJobExitHandler(id string) func(error) {
 	return func(err error) {
 		now := time.Now().UTC()
@@ -764,6 +981,7 @@ func (s *server) makeJobExitHandler(id string) func(error) {
 		}
 		delete(s.processes, id)
 		s.mu.Unlock()
+		s.stopFrameLoop(id)
 		jobLogger := s.jobLogger(id, meta)
 		if meta != nil {
 			if saveErr := s.store.SaveJob(meta); saveErr != nil {
@@ -793,17 +1011,34 @@ func (s *server) makeUploadExitHandler(id string) func(error) {
 		now := time.Now().UTC()
 		var meta *uploadJob
 		var publish bool
+		var outputPath string
 		s.mu.Lock()
 		if up, ok := s.uploads[id]; ok {
-			if up.CompletedAt == nil {
-				up.CompletedAt = &now
-			}
 			meta = up
 			publish = err == nil
+			outputPath = up.OutputPath
 		}
 		delete(s.processes, id)
 		s.mu.Unlock()
 		uploadLogger := s.uploadLogger(id, meta)
+		if meta != nil {
+			// Measure before marking the upload completed so a status poll
+			// never sees it completed without its size.
+			var size int64
+			if publish {
+				var sizeErr error
+				if size, sizeErr = outputSize(outputPath); sizeErr != nil && uploadLogger != nil {
+					uploadLogger.Warn("measure upload output", "error", sizeErr)
+				}
+			}
+			s.mu.Lock()
+			meta.OutputBytes = size
+			meta.Failed = !publish
+			if meta.CompletedAt == nil {
+				meta.CompletedAt = &now
+			}
+			s.mu.Unlock()
+		}
 		if publish && meta != nil {
 			if err := s.publishUpload(meta); err != nil {
 				if uploadLogger != nil {
@@ -864,6 +1099,9 @@ func cloneRenditions(src []rendition) []rendition {
 	return out
 }
 
+// defaultRendition is transcoded when a job requests no renditions.
+var defaultRendition = rendition{Name: "720p", Bitrate: 2800}
+
 type transcodePlan struct {
 	args       []string
 	renditions []rendition
@@ -871,13 +1109,30 @@ type transcodePlan struct {
 	master     string
 }
 
-func buildTranscodePlan(input, outputDir string, ladder []rendition) (*transcodePlan, error) {
+// buildTranscodePlan prepares the ffmpeg arguments for ladder. Non-zero
+// limits clamp the ladder first; a ladder that cannot be fitted is returned
+// as *ingest.TranscodeLimitError before anything is written to outputDir.
+func buildTranscodePlan(input, outputDir string, ladder []rendition, limits models.TranscodeLimits) (*transcodePlan, error) {
 	if strings.TrimSpace(input) == "" {
 		return nil, fmt.Errorf("input source is required")
 	}
 	if strings.TrimSpace(outputDir) == "" {
 		return nil, fmt.Errorf("output directory is required")
 	}
+
+	updated := make([]rendition, len(ladder))
+	copy(updated, ladder)
+	if len(updated) == 0 {
+		updated = append(updated, defaultRendition)
+	}
+	if !limits.IsZero() {
+		clamped, err := clampLadder(updated, limits)
+		if err != nil {
+			return nil, err
+		}
+		updated = clamped
+	}
+
 	absDir, err := filepath.Abs(outputDir)
 	if err != nil {
 		return nil, err
@@ -886,12 +1141,6 @@ func buildTranscodePlan(input, outputDir string, ladder []rendition) (*transcode
 		return nil, err
 	}
 
-	updated := make([]rendition, len(ladder))
-	copy(updated, ladder)
-	if len(updated) == 0 {
-		updated = append(updated, rendition{Name: "720p", Bitrate: 2800})
-	}
-
 	count := len(updated)
 	master := filepath.ToSlash(filepath.Join(absDir, "index.m3u8"))
 	variantNames := make([]string, count)
@@ -1009,8 +1258,8 @@ func buildTranscodePlan(input, outputDir string, ladder []rendition) (*transcode
 
 	args = append(args,
 		"-f", "hls",
-		"-hls_time", "4",
-		"-hls_list_size", "6",
+		"-hls_time", strconv.Itoa(hlsSegmentSeconds),
+		"-hls_list_size", strconv.Itoa(hlsPlaylistSegments),
 		"-hls_flags", "delete_segments+program_date_time+independent_segments",
 		"-master_pl_name", "index.m3u8",
 		"-hls_segment_filename", segmentPattern,
@@ -1026,6 +1275,36 @@ func buildTranscodePlan(input, outputDir string, ladder []rendition) (*transcode
 	}, nil
 }
 
+// clampLadder fits ladder inside limits with ingest.ClampLadder, the same
+// rules the API applies when it checks a requested ladder. Rungs without a
+// bitrate are costed at the default for their height.
+func clampLadder(ladder []rendition, limits models.TranscodeLimits) ([]rendition, error) {
+	costed := make([]ingest.Rendition, len(ladder))
+	for idx, rung := range ladder {
+		bitrate := rung.Bitrate
+		if bitrate <= 0 {
+			_, height := resolveDimensions(rung.Name)
+			bitrate = defaultVideoBitrate(height)
+		}
+		costed[idx] = ingest.Rendition{Name: rung.Name, Bitrate: bitrate}
+	}
+	kept, err := ingest.ClampLadder(costed, limits)
+	if err != nil {
+		return nil, err
+	}
+	// kept preserves ladder order, so walk both slices to recover the
+	// original rungs.
+	clamped := make([]rendition, 0, len(kept))
+	next := 0
+	for idx := range costed {
+		if next < len(kept) && costed[idx] == kept[next] {
+			clamped = append(clamped, ladder[idx])
+			next++
+		}
+	}
+	return clamped, nil
+}
+
 func (s *server) startFFmpeg(jobID string, plan *transcodePlan, onExit func(error)) (*processState, error) {
 	if plan == nil {
 		return nil, fmt.Errorf("transcode plan is required")
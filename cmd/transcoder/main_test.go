@@ -3,6 +3,9 @@ package main
 import (
 	"bufio"
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
@@ -20,35 +23,49 @@ import (
 	"testing"
 	"time"
 
+	"bitriver-live/internal/ingest"
+	"bitriver-live/internal/models"
 	"bitriver-live/internal/observability/metrics"
 )
 
 const testToken = "test-token"
 
 func useStubFFmpeg(t *testing.T) string {
+	t.Helper()
+	return useStubBinary(t, "ffmpeg")
+}
+
+func useStubFFprobe(t *testing.T) string {
+	t.Helper()
+	return useStubBinary(t, "ffprobe")
+}
+
+// useStubBinary puts testdata, which holds the ffmpeg and ffprobe stubs, at
+// the front of PATH.
+func useStubBinary(t *testing.T, name string) string {
 	t.Helper()
 	_, testFile, _, ok := runtime.Caller(0)
 	if !ok {
 		t.Fatal("determine test file path")
 	}
-	stubPath, err := filepath.Abs(filepath.Join(filepath.Dir(testFile), "testdata", "ffmpeg"))
+	stubPath, err := filepath.Abs(filepath.Join(filepath.Dir(testFile), "testdata", name))
 	if err != nil {
-		t.Fatalf("resolve ffmpeg stub: %v", err)
+		t.Fatalf("resolve %s stub: %v", name, err)
 	}
 	if _, err := os.Stat(stubPath); err != nil {
-		t.Fatalf("stat ffmpeg stub: %v", err)
+		t.Fatalf("stat %s stub: %v", name, err)
 	}
 	pathList := []string{filepath.Dir(stubPath)}
 	if existing := os.Getenv("PATH"); existing != "" {
 		pathList = append(pathList, existing)
 	}
 	t.Setenv("PATH", strings.Join(pathList, string(os.PathListSeparator)))
-	resolved, err := exec.LookPath("ffmpeg")
+	resolved, err := exec.LookPath(name)
 	if err != nil {
-		t.Fatalf("ffmpeg stub not on PATH: %v", err)
+		t.Fatalf("%s stub not on PATH: %v", name, err)
 	}
 	if resolved != stubPath {
-		t.Fatalf("unexpected ffmpeg path: %s", resolved)
+		t.Fatalf("unexpected %s path: %s", name, resolved)
 	}
 	return stubPath
 }
@@ -91,6 +108,7 @@ func startStubTranscoder(t *testing.T, tempDir string, exitErr *atomic.Pointer[e
 	if err != nil {
 		t.Fatalf("new server: %v", err)
 	}
+	srv.prober = nil
 	srv.launchProcess = func(id string, plan *transcodePlan, onExit func(error)) (*processState, error) {
 		done := make(chan struct{})
 		var once atomic.Bool
@@ -431,10 +449,18 @@ func TestJobProducesSegmentsAndCanBeStopped(t *testing.T) {
 	if err != nil {
 		t.Fatalf("delete job: %v", err)
 	}
+	var stopResp jobStopResponse
+	decodeErr := json.NewDecoder(respDel.Body).Decode(&stopResp)
 	_ = respDel.Body.Close()
-	if respDel.StatusCode != http.StatusNoContent {
+	if respDel.StatusCode != http.StatusOK {
 		t.Fatalf("unexpected delete status: %d", respDel.StatusCode)
 	}
+	if decodeErr != nil {
+		t.Fatalf("decode delete response: %v", decodeErr)
+	}
+	if stopResp.JobID != jobID2 || stopResp.OutputBytes <= 0 {
+		t.Fatalf("expected the stopped job's output size, got %+v", stopResp)
+	}
 
 	waitFor(t, 30*time.Second, func() bool {
 		srv.mu.RLock()
@@ -584,6 +610,27 @@ func TestUploadPublishesHTTPPlayback(t *testing.T) {
 	if persisted.CompletedAt == nil {
 		t.Fatal("expected completed timestamp for upload")
 	}
+	if persisted.OutputBytes <= 0 {
+		t.Fatalf("expected the upload's output size to be recorded, got %d", persisted.OutputBytes)
+	}
+	statusReq, err := http.NewRequest(http.MethodGet, ts.URL+"/v1/uploads/"+uploadResp.JobID, nil)
+	if err != nil {
+		t.Fatalf("build status request: %v", err)
+	}
+	statusReq.Header.Set("Authorization", authHeader)
+	statusResp, err := client.Do(statusReq)
+	if err != nil {
+		t.Fatalf("get upload status: %v", err)
+	}
+	var status uploadStatusResponse
+	decodeErr := json.NewDecoder(statusResp.Body).Decode(&status)
+	_ = statusResp.Body.Close()
+	if statusResp.StatusCode != http.StatusOK || decodeErr != nil {
+		t.Fatalf("unexpected upload status response: %d %v", statusResp.StatusCode, decodeErr)
+	}
+	if status.Status != "completed" || status.OutputBytes != persisted.OutputBytes {
+		t.Fatalf("expected completed status with %d output bytes, got %+v", persisted.OutputBytes, status)
+	}
 	if persisted.Playback != expectedPlayback {
 		t.Fatalf("unexpected persisted playback url: %s", persisted.Playback)
 	}
@@ -689,6 +736,97 @@ func TestHandleJobsMetricsOnFailure(t *testing.T) {
 	}
 }
 
+func TestBuildTranscodePlanAppliesLimits(t *testing.T) {
+	tempDir := t.TempDir()
+	ladder := []rendition{{Name: "1080p", Bitrate: 6000}, {Name: "720p", Bitrate: 3000}, {Name: "480p"}}
+
+	plan, err := buildTranscodePlan("rtmp://origin/live", filepath.Join(tempDir, "clamped"), ladder, models.TranscodeLimits{MaxHeight: 720, MaxBitrate: 5200})
+	if err != nil {
+		t.Fatalf("build plan: %v", err)
+	}
+	names := make([]string, 0, len(plan.renditions))
+	total := 0
+	for _, rung := range plan.renditions {
+		names = append(names, rung.Name)
+		total += rung.VideoBitrate
+	}
+	if strings.Join(names, ",") != "720p,480p" {
+		t.Fatalf("expected 1080p to be clamped away, got %v", names)
+	}
+	if total > 5200 {
+		t.Fatalf("expected clamped video bitrate within 5200 kbps, got %d", total)
+	}
+	if strings.Contains(strings.Join(plan.args, " "), "1080p") {
+		t.Fatalf("expected ffmpeg args to omit the 1080p variant: %v", plan.args)
+	}
+
+	rejectedDir := filepath.Join(tempDir, "rejected")
+	_, err = buildTranscodePlan("rtmp://origin/live", rejectedDir, ladder, models.TranscodeLimits{MaxBitrate: 500})
+	var limitErr *ingest.TranscodeLimitError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("expected TranscodeLimitError, got %v", err)
+	}
+	if limitErr.Reason != ingest.LimitReasonBitrate || limitErr.Limit != 500 {
+		t.Fatalf("unexpected rejection: %+v", limitErr)
+	}
+	if _, statErr := os.Stat(rejectedDir); !os.IsNotExist(statErr) {
+		t.Fatalf("expected rejected plan to leave no output directory, stat err %v", statErr)
+	}
+}
+
+func TestHandleJobsRejectsLadderOverLimits(t *testing.T) {
+	metrics.Default().Reset()
+	t.Cleanup(metrics.Default().Reset)
+
+	tempDir := t.TempDir()
+	t.Setenv("BITRIVER_TRANSCODER_PUBLIC_BASE_URL", "https://cdn.example.com/hls")
+	t.Setenv("BITRIVER_TRANSCODER_PUBLIC_DIR", filepath.Join(tempDir, "public"))
+
+	srv, err := newServer(testToken, tempDir, newTestLogger(), newTestRegistry())
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	launched := false
+	srv.launchProcess = func(jobID string, plan *transcodePlan, onExit func(error)) (*processState, error) {
+		launched = true
+		return &processState{cancel: func() {}, done: make(chan struct{})}, nil
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"channelId":  "channel-1",
+		"sessionId":  "session-1",
+		"originUrl":  "https://cdn/source.m3u8",
+		"renditions": []map[string]any{{"name": "1080p", "bitrate": 6000}},
+		"limits":     map[string]any{"maxHeight": 720},
+	})
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/v1/jobs", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	res := httptest.NewRecorder()
+
+	srv.handleJobs(res, req)
+
+	if res.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d: %s", res.Code, res.Body.String())
+	}
+	var rejection ingest.TranscodeLimitError
+	if err := json.Unmarshal(res.Body.Bytes(), &rejection); err != nil {
+		t.Fatalf("decode rejection: %v", err)
+	}
+	if rejection.Reason != ingest.LimitReasonResolution || rejection.Limit != 720 || rejection.Value != 1080 {
+		t.Fatalf("unexpected rejection: %+v", rejection)
+	}
+	if launched {
+		t.Fatal("expected no ffmpeg process for a rejected ladder")
+	}
+	events, _ := metrics.Default().TranscoderJobCounts()
+	if events[metrics.TranscoderJobLabel{Kind: "live", Status: "fail"}] != 1 {
+		t.Fatalf("expected one live failure, got %d", events[metrics.TranscoderJobLabel{Kind: "live", Status: "fail"}])
+	}
+}
+
 func TestHandleUploadsRecordsMetrics(t *testing.T) {
 	metrics.Default().Reset()
 	t.Cleanup(metrics.Default().Reset)
@@ -701,6 +839,7 @@ func TestHandleUploadsRecordsMetrics(t *testing.T) {
 	if err != nil {
 		t.Fatalf("new server: %v", err)
 	}
+	srv.prober = nil
 	srv.launchProcess = func(jobID string, plan *transcodePlan, onExit func(error)) (*processState, error) {
 		return &processState{cancel: func() {}, done: make(chan struct{})}, nil
 	}
@@ -749,6 +888,7 @@ func TestHandleUploadsMetricsOnFailure(t *testing.T) {
 	if err != nil {
 		t.Fatalf("new server: %v", err)
 	}
+	srv.prober = nil
 	srv.launchProcess = func(jobID string, plan *transcodePlan, onExit func(error)) (*processState, error) {
 		return nil, errors.New("ffmpeg missing")
 	}
@@ -806,6 +946,10 @@ func TestExitHandlersRecordMetrics(t *testing.T) {
 	srv.makeJobExitHandler(liveID)(nil)
 	srv.makeUploadExitHandler(uploadID)(errors.New("ffmpeg error"))
 
+	if up := srv.uploads[uploadID]; !up.Failed || up.CompletedAt == nil || up.OutputBytes != 0 {
+		t.Fatalf("expected the failed upload marked failed without output, got %+v", up)
+	}
+
 	events, active := metrics.Default().TranscoderJobCounts()
 	if events[metrics.TranscoderJobLabel{Kind: "live", Status: "complete"}] != 1 {
 		t.Fatalf("expected one live completion, got %d", events[metrics.TranscoderJobLabel{Kind: "live", Status: "complete"}])
@@ -1005,3 +1149,862 @@ func fetchHealth(t *testing.T, ts *httptest.Server) (healthResponse, int) {
 	}
 	return payload, resp.StatusCode
 }
+
+type fakeProber struct {
+	probe mediaProbe
+	err   error
+}
+
+func (f fakeProber) Probe(context.Context, string) (mediaProbe, error) {
+	return f.probe, f.err
+}
+
+func TestHandleUploadsValidatesProbedMedia(t *testing.T) {
+	cases := []struct {
+		name       string
+		prober     fakeProber
+		wantStatus int
+		wantReason string
+	}{
+		{
+			name:       "audio only",
+			prober:     fakeProber{probe: mediaProbe{DurationSeconds: 30, AudioCodec: "aac"}},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantReason: rejectNotVideo,
+		},
+		{
+			name:       "too long",
+			prober:     fakeProber{probe: mediaProbe{DurationSeconds: 13 * 3600, Width: 1920, Height: 1080, VideoCodec: "h264"}},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantReason: rejectTooLong,
+		},
+		{
+			name:       "resolution too large",
+			prober:     fakeProber{probe: mediaProbe{DurationSeconds: 60, Width: 7680, Height: 4320, VideoCodec: "hevc"}},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantReason: rejectResolutionTooBig,
+		},
+		{
+			name:       "unreadable",
+			prober:     fakeProber{err: &probeRejection{Reason: rejectUnreadable, Message: "file could not be read as media"}},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantReason: rejectUnreadable,
+		},
+		{
+			name:       "transient failure",
+			prober:     fakeProber{err: errors.New("connection refused")},
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "portrait resolution too large",
+			prober:     fakeProber{probe: mediaProbe{DurationSeconds: 60, Width: 2880, Height: 3840, VideoCodec: "hevc"}},
+			wantStatus: http.StatusUnprocessableEntity,
+			wantReason: rejectResolutionTooBig,
+		},
+		{
+			name:       "missing ffprobe",
+			prober:     fakeProber{err: errProberUnavailable},
+			wantStatus: http.StatusServiceUnavailable,
+		},
+		{
+			name:       "valid video",
+			prober:     fakeProber{probe: mediaProbe{DurationSeconds: 60, Width: 1920, Height: 1080, VideoCodec: "h264", AudioCodec: "aac"}},
+			wantStatus: http.StatusAccepted,
+		},
+		{
+			name:       "valid portrait video",
+			prober:     fakeProber{probe: mediaProbe{DurationSeconds: 60, Width: 2160, Height: 3840, VideoCodec: "h264", AudioCodec: "aac"}},
+			wantStatus: http.StatusAccepted,
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			t.Setenv("BITRIVER_TRANSCODER_PUBLIC_BASE_URL", "https://cdn.example.com/hls")
+			t.Setenv("BITRIVER_TRANSCODER_PUBLIC_DIR", filepath.Join(tempDir, "public"))
+
+			srv, err := newServer(testToken, tempDir, newTestLogger(), newTestRegistry())
+			if err != nil {
+				t.Fatalf("new server: %v", err)
+			}
+			srv.prober = tc.prober
+			launched := false
+			srv.launchProcess = func(jobID string, plan *transcodePlan, onExit func(error)) (*processState, error) {
+				launched = true
+				return &processState{cancel: func() {}, done: make(chan struct{})}, nil
+			}
+
+			body, err := json.Marshal(map[string]any{
+				"channelId":  "channel-1",
+				"uploadId":   "upload-1",
+				"sourceUrl":  "https://cdn/source.mp4",
+				"filename":   "source.mp4",
+				"renditions": []map[string]any{{"name": "720p", "bitrate": 2000}},
+			})
+			if err != nil {
+				t.Fatalf("marshal request: %v", err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/v1/uploads", bytes.NewReader(body))
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			res := httptest.NewRecorder()
+
+			srv.handleUploads(res, req)
+
+			if res.Code != tc.wantStatus {
+				t.Fatalf("unexpected status: got %d want %d (%s)", res.Code, tc.wantStatus, res.Body.String())
+			}
+			if tc.wantStatus == http.StatusAccepted {
+				if !launched {
+					t.Fatal("expected transcode to launch for valid media")
+				}
+				var payload uploadResponse
+				if err := json.Unmarshal(res.Body.Bytes(), &payload); err != nil {
+					t.Fatalf("decode response: %v", err)
+				}
+				if payload.Media == nil || payload.Media.VideoCodec != "h264" || payload.Media.Width != tc.prober.probe.Width {
+					t.Fatalf("expected probe metadata in response, got %+v", payload.Media)
+				}
+				return
+			}
+			if launched {
+				t.Fatal("expected transcode not to launch")
+			}
+			if tc.wantReason == "" {
+				return
+			}
+			var rejection probeRejection
+			if err := json.Unmarshal(res.Body.Bytes(), &rejection); err != nil {
+				t.Fatalf("decode rejection: %v", err)
+			}
+			if rejection.Reason != tc.wantReason {
+				t.Fatalf("unexpected reason: got %q want %q", rejection.Reason, tc.wantReason)
+			}
+		})
+	}
+}
+
+func TestFFprobeProberParsesReport(t *testing.T) {
+	useStubFFprobe(t)
+
+	probe, err := newFFprobeProber().Probe(context.Background(), "https://cdn/source.mp4")
+	if err != nil {
+		t.Fatalf("probe: %v", err)
+	}
+	if probe.VideoCodec != "h264" || probe.AudioCodec != "aac" || probe.Width != 1280 || probe.Height != 720 || probe.DurationSeconds != 10 {
+		t.Fatalf("unexpected probe %+v", probe)
+	}
+}
+
+func TestProbeUploadWithoutFFprobe(t *testing.T) {
+	t.Setenv("PATH", t.TempDir())
+	srv := &server{prober: newFFprobeProber(), logger: newTestLogger()}
+
+	if _, err := srv.probeUpload(context.Background(), "https://cdn/source.mp4"); !errors.Is(err, errProberUnavailable) {
+		t.Fatalf("expected uploads to be refused without ffprobe, got %v", err)
+	}
+
+	srv.uploadLimits.AllowUnprobed = true
+	probe, err := srv.probeUpload(context.Background(), "https://cdn/source.mp4")
+	if err != nil || probe != nil {
+		t.Fatalf("expected the upload to skip validation when allowed, got %+v, %v", probe, err)
+	}
+}
+
+func TestHandleUploadsVerifiesExpectedHash(t *testing.T) {
+	source := []byte("original upload bytes")
+	sum := sha256.Sum256(source)
+	expected := hex.EncodeToString(sum[:])
+	origin := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(source)
+	}))
+	defer origin.Close()
+
+	cases := []struct {
+		name       string
+		hash       string
+		wantStatus int
+	}{
+		{name: "matching source", hash: strings.ToUpper(expected), wantStatus: http.StatusAccepted},
+		{name: "changed source", hash: strings.Repeat("0", 64), wantStatus: http.StatusUnprocessableEntity},
+		{name: "malformed hash", hash: "not-a-hash", wantStatus: http.StatusBadRequest},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			tempDir := t.TempDir()
+			t.Setenv("BITRIVER_TRANSCODER_PUBLIC_BASE_URL", "https://cdn.example.com/hls")
+			t.Setenv("BITRIVER_TRANSCODER_PUBLIC_DIR", filepath.Join(tempDir, "public"))
+
+			srv, err := newServer(testToken, tempDir, newTestLogger(), newTestRegistry())
+			if err != nil {
+				t.Fatalf("new server: %v", err)
+			}
+			srv.prober = nil
+			launched := false
+			srv.launchProcess = func(jobID string, plan *transcodePlan, onExit func(error)) (*processState, error) {
+				launched = true
+				return &processState{cancel: func() {}, done: make(chan struct{})}, nil
+			}
+
+			body, err := json.Marshal(map[string]any{
+				"channelId":    "channel-1",
+				"uploadId":     "upload-1",
+				"sourceUrl":    origin.URL + "/source.mp4",
+				"expectedHash": tc.hash,
+			})
+			if err != nil {
+				t.Fatalf("marshal request: %v", err)
+			}
+			req := httptest.NewRequest(http.MethodPost, "/v1/uploads", bytes.NewReader(body))
+			req.Header.Set("Authorization", "Bearer "+testToken)
+			res := httptest.NewRecorder()
+
+			srv.handleUploads(res, req)
+
+			if res.Code != tc.wantStatus {
+				t.Fatalf("unexpected status: got %d want %d (%s)", res.Code, tc.wantStatus, res.Body.String())
+			}
+			if launched != (tc.wantStatus == http.StatusAccepted) {
+				t.Fatalf("expected launched=%v, got %v", tc.wantStatus == http.StatusAccepted, launched)
+			}
+			if tc.wantStatus != http.StatusUnprocessableEntity {
+				return
+			}
+			var rejection probeRejection
+			if err := json.Unmarshal(res.Body.Bytes(), &rejection); err != nil {
+				t.Fatalf("decode rejection: %v", err)
+			}
+			if rejection.Reason != rejectHashMismatch {
+				t.Fatalf("expected reason %q, got %+v", rejectHashMismatch, rejection)
+			}
+		})
+	}
+}
+
+func TestParseFFprobeOutput(t *testing.T) {
+	data := []byte(`{"streams":[{"codec_type":"audio","codec_name":"aac"},{"codec_type":"video","codec_name":"h264","width":1280,"height":720}],"format":{"format_name":"mp4","duration":"42.5","bit_rate":"2500000"}}`)
+	probe, err := parseFFprobeOutput(data)
+	if err != nil {
+		t.Fatalf("parse: %v", err)
+	}
+	if probe.VideoCodec != "h264" || probe.AudioCodec != "aac" || probe.Width != 1280 || probe.Height != 720 || probe.DurationSeconds != 42.5 || probe.Bitrate != 2500 {
+		t.Fatalf("unexpected probe: %+v", probe)
+	}
+}
+
+func TestHandleUploadsFitsLadderToSource(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("BITRIVER_TRANSCODER_PUBLIC_BASE_URL", "https://cdn.example.com/hls")
+	t.Setenv("BITRIVER_TRANSCODER_PUBLIC_DIR", filepath.Join(tempDir, "public"))
+
+	srv, err := newServer(testToken, tempDir, newTestLogger(), newTestRegistry())
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	srv.prober = fakeProber{probe: mediaProbe{DurationSeconds: 60, Width: 720, Height: 1280, VideoCodec: "h264"}}
+	var launched *transcodePlan
+	srv.launchProcess = func(jobID string, plan *transcodePlan, onExit func(error)) (*processState, error) {
+		launched = plan
+		return &processState{cancel: func() {}, done: make(chan struct{})}, nil
+	}
+
+	body, err := json.Marshal(map[string]any{
+		"channelId": "channel-1",
+		"uploadId":  "upload-1",
+		"sourceUrl": "https://cdn/vertical.mp4",
+		"filename":  "vertical.mp4",
+		"renditions": []map[string]any{
+			{"name": "1080p", "bitrate": 6000},
+			{"name": "720p", "bitrate": 3000},
+			{"name": "480p", "bitrate": 1500},
+		},
+	})
+	if err != nil {
+		t.Fatalf("marshal request: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/v1/uploads", bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	res := httptest.NewRecorder()
+
+	srv.handleUploads(res, req)
+
+	if res.Code != http.StatusAccepted {
+		t.Fatalf("unexpected status: %d (%s)", res.Code, res.Body.String())
+	}
+	if launched == nil {
+		t.Fatal("expected transcode to launch")
+	}
+	names := make([]string, 0, len(launched.renditions))
+	for _, rendition := range launched.renditions {
+		names = append(names, rendition.Name)
+	}
+	if strings.Join(names, ",") != "720p,480p" {
+		t.Fatalf("expected 1080p to be skipped for a 720-wide vertical source, got %v", names)
+	}
+}
+
+func TestFrameSourcePrefersTallestRendition(t *testing.T) {
+	renditions := []rendition{
+		{Name: "480p", ManifestURL: "/work/480p/index.m3u8", Height: 480, Bitrate: 1500},
+		{Name: "1080p", ManifestURL: "/work/1080p/index.m3u8", Height: 1080, Bitrate: 6000},
+		{Name: "720p", ManifestURL: "/work/720p/index.m3u8", Height: 720, Bitrate: 3000},
+	}
+	if got := frameSource(renditions); got != filepath.FromSlash("/work/1080p/index.m3u8") {
+		t.Fatalf("expected 1080p manifest, got %q", got)
+	}
+	if got := frameSource(nil); got != "" {
+		t.Fatalf("expected no source without renditions, got %q", got)
+	}
+}
+
+func TestPreviewSourcePrefersShortestRendition(t *testing.T) {
+	renditions := []rendition{
+		{Name: "720p", ManifestURL: "/work/720p/index.m3u8", Height: 720, Bitrate: 3000},
+		{Name: "480p-high", ManifestURL: "/work/480p-high/index.m3u8", Height: 480, Bitrate: 2000},
+		{Name: "480p", ManifestURL: "/work/480p/index.m3u8", Height: 480, Bitrate: 1500},
+		{Name: "360p", Height: 360, Bitrate: 800},
+	}
+	if got := previewSource(renditions); got != filepath.FromSlash("/work/480p/index.m3u8") {
+		t.Fatalf("expected the lowest 480p manifest, got %q", got)
+	}
+	if got := previewSource(nil); got != "" {
+		t.Fatalf("expected no source without renditions, got %q", got)
+	}
+}
+
+func TestCaptureJobFrameSkipsWhileBusy(t *testing.T) {
+	outputDir := t.TempDir()
+	meta := &job{ID: "job-1", OutputPath: outputDir, Renditions: []rendition{{Name: "480p", ManifestURL: filepath.Join(outputDir, "480p", "index.m3u8"), Height: 480}}}
+	started := make(chan struct{})
+	release := make(chan struct{})
+	var captures atomic.Int32
+	srv := &server{captureFrame: func(ctx context.Context, input, dest string) error {
+		if captures.Add(1) == 1 {
+			close(started)
+			<-release
+		}
+		return os.WriteFile(dest, []byte{0xff, 0xd8, 0xff, 0xd9}, 0o644)
+	}}
+
+	firstErr := make(chan error, 1)
+	go func() { firstErr <- srv.captureJobFrame(context.Background(), meta) }()
+	<-started
+	if err := srv.captureJobFrame(context.Background(), meta); !errors.Is(err, errFrameCaptureBusy) {
+		t.Fatalf("expected an overlapping capture to be skipped, got %v", err)
+	}
+	close(release)
+	if err := <-firstErr; err != nil {
+		t.Fatalf("first capture: %v", err)
+	}
+	if err := srv.captureJobFrame(context.Background(), meta); err != nil {
+		t.Fatalf("expected a capture after the previous one finished, got %v", err)
+	}
+	if got := captures.Load(); got != 2 {
+		t.Fatalf("expected ffmpeg to run twice, got %d", got)
+	}
+}
+
+func TestFrameLoopCapturesOnInterval(t *testing.T) {
+	outputDir := t.TempDir()
+	meta := &job{ID: "job-1", OutputPath: outputDir, Renditions: []rendition{{Name: "480p", ManifestURL: filepath.Join(outputDir, "480p", "index.m3u8"), Height: 480}}}
+	captured := make(chan string, 8)
+	srv := &server{
+		frameInterval: 10 * time.Millisecond,
+		frameLoops:    make(map[string]*frameLoop),
+		captureFrame: func(ctx context.Context, input, dest string) error {
+			select {
+			case captured <- input:
+			default:
+			}
+			return os.WriteFile(dest, []byte{0xff, 0xd8, 0xff, 0xd9}, 0o644)
+		},
+	}
+	srv.startFrameLoop(meta)
+	for i := 0; i < 2; i++ {
+		select {
+		case input := <-captured:
+			if input != meta.Renditions[0].ManifestURL {
+				t.Fatalf("expected capture from the 480p manifest, got %q", input)
+			}
+		case <-time.After(2 * time.Second):
+			t.Fatalf("expected capture %d within the interval", i+1)
+		}
+	}
+	srv.stopFrameLoop(meta.ID)
+	if _, err := os.Stat(previewFramePath(meta)); err != nil {
+		t.Fatalf("expected a preview frame on disk: %v", err)
+	}
+	for len(captured) > 0 {
+		<-captured
+	}
+	time.Sleep(30 * time.Millisecond)
+	if len(captured) != 0 {
+		t.Fatal("expected no captures after the loop stopped")
+	}
+}
+
+func TestCaptureJobFrameWithFFmpeg(t *testing.T) {
+	useStubFFmpeg(t)
+	tempDir := t.TempDir()
+	var exitErr atomic.Pointer[error]
+	srv, _ := startStubTranscoder(t, tempDir, &exitErr)
+	srv.captureFrame = ffmpegFrameCapture
+
+	plan, err := buildTranscodePlan("rtmp://origin/live", filepath.Join(tempDir, "live", "job-1"), []rendition{{Name: "720p"}, {Name: "1080p"}}, models.TranscodeLimits{})
+	if err != nil {
+		t.Fatalf("build plan: %v", err)
+	}
+	meta := &job{ID: "job-1", Renditions: plan.renditions, OutputPath: plan.outputDir}
+	if err := srv.captureJobFrame(context.Background(), meta); err != nil {
+		t.Fatalf("captureJobFrame: %v", err)
+	}
+	data, err := os.ReadFile(filepath.Join(plan.outputDir, previewFrameName))
+	if err != nil {
+		t.Fatalf("read preview frame: %v", err)
+	}
+	if !bytes.HasPrefix(data, []byte{0xff, 0xd8}) {
+		t.Fatalf("expected JPEG preview, got %x", data)
+	}
+	entries, err := os.ReadDir(plan.outputDir)
+	if err != nil {
+		t.Fatalf("read output dir: %v", err)
+	}
+	for _, entry := range entries {
+		if strings.HasPrefix(entry.Name(), ".frame-") {
+			t.Fatalf("expected temporary frame to be cleaned up, found %s", entry.Name())
+		}
+	}
+}
+
+func TestJobFrameEndpoint(t *testing.T) {
+	tempDir := t.TempDir()
+	var exitErr atomic.Pointer[error]
+	srv, ts := startStubTranscoder(t, tempDir, &exitErr)
+	srv.launchProcess = func(id string, plan *transcodePlan, onExit func(error)) (*processState, error) {
+		done := make(chan struct{})
+		var once atomic.Bool
+		return &processState{cancel: func() {
+			if once.CompareAndSwap(false, true) {
+				close(done)
+			}
+		}, done: done}, nil
+	}
+	var captures atomic.Int32
+	var captureInput atomic.Pointer[string]
+	srv.captureFrame = func(ctx context.Context, input, dest string) error {
+		captures.Add(1)
+		captureInput.Store(&input)
+		return os.WriteFile(dest, []byte{0xff, 0xd8, 0xff, 0xd9}, 0o644)
+	}
+	srv.frameInterval = time.Hour
+
+	submitJob(t, ts, "rtmp://origin/live")
+	srv.mu.RLock()
+	var jobID string
+	for id := range srv.jobs {
+		jobID = id
+	}
+	srv.mu.RUnlock()
+	if jobID == "" {
+		t.Fatal("expected a running job")
+	}
+
+	get := func(path, token string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodGet, ts.URL+path, nil)
+		if err != nil {
+			t.Fatalf("build request: %v", err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatalf("get %s: %v", path, err)
+		}
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+
+	if resp := get("/v1/jobs/"+jobID+"/frame", ""); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", resp.StatusCode)
+	}
+	if resp := get("/v1/jobs/unknown/frame", testToken); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown job, got %d", resp.StatusCode)
+	}
+
+	resp := get("/v1/jobs/"+jobID+"/frame", testToken)
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200, got %d", resp.StatusCode)
+	}
+	if got := resp.Header.Get("Content-Type"); got != "image/jpeg" {
+		t.Fatalf("expected image/jpeg, got %q", got)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	if !bytes.Equal(body, []byte{0xff, 0xd8, 0xff, 0xd9}) {
+		t.Fatalf("unexpected frame body %x", body)
+	}
+	if input := captureInput.Load(); input == nil || !strings.HasSuffix(filepath.ToSlash(*input), "/720p/index.m3u8") {
+		t.Fatalf("expected capture from the 720p manifest, got %v", input)
+	}
+
+	get("/v1/jobs/"+jobID+"/frame", testToken)
+	if got := captures.Load(); got != 1 {
+		t.Fatalf("expected a fresh frame to be reused, got %d captures", got)
+	}
+
+	req, err := http.NewRequest(http.MethodDelete, ts.URL+"/v1/jobs/"+jobID, nil)
+	if err != nil {
+		t.Fatalf("build delete: %v", err)
+	}
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	delResp, err := ts.Client().Do(req)
+	if err != nil {
+		t.Fatalf("delete job: %v", err)
+	}
+	_ = delResp.Body.Close()
+	if delResp.StatusCode != http.StatusOK {
+		t.Fatalf("expected 200 on delete, got %d", delResp.StatusCode)
+	}
+	srv.mu.RLock()
+	loops := len(srv.frameLoops)
+	srv.mu.RUnlock()
+	if loops != 0 {
+		t.Fatalf("expected frame loop to stop with the job, got %d running", loops)
+	}
+}
+
+func TestApplyClipBufferRetainsSegments(t *testing.T) {
+	srv := &server{clipBuffer: 60 * time.Second}
+	plan, err := buildTranscodePlan("rtmp://origin/live", filepath.Join(t.TempDir(), "live", "job-1"), nil, models.TranscodeLimits{})
+	if err != nil {
+		t.Fatalf("build plan: %v", err)
+	}
+	output := plan.args[len(plan.args)-1]
+	srv.applyClipBuffer(plan)
+	if got := plan.args[len(plan.args)-1]; got != output {
+		t.Fatalf("expected output path to stay last, got %q", got)
+	}
+	joined := strings.Join(plan.args, " ")
+	if !strings.Contains(joined, "-hls_delete_threshold 15 ") {
+		t.Fatalf("expected 60s of 4s segments to be retained, got %s", joined)
+	}
+
+	disabled := &server{}
+	plan, err = buildTranscodePlan("rtmp://origin/live", filepath.Join(t.TempDir(), "live", "job-2"), nil, models.TranscodeLimits{})
+	if err != nil {
+		t.Fatalf("build plan: %v", err)
+	}
+	disabled.applyClipBuffer(plan)
+	if strings.Contains(strings.Join(plan.args, " "), "-hls_delete_threshold") {
+		t.Fatal("expected no extra retention without a clip buffer")
+	}
+}
+
+// writeFixtureSegments writes count numbered segments into dir and a media
+// playlist listing all but the last, which stands in for the segment ffmpeg
+// is still writing.
+func writeFixtureSegments(t *testing.T, dir string, count int) {
+	t.Helper()
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		t.Fatalf("prepare rendition dir: %v", err)
+	}
+	playlist := []string{"#EXTM3U", "#EXT-X-VERSION:3"}
+	for idx := 0; idx < count; idx++ {
+		name := fmt.Sprintf("segment_%06d.ts", idx)
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(fmt.Sprintf("seg%d;", idx)), 0o644); err != nil {
+			t.Fatalf("write segment: %v", err)
+		}
+		if idx < count-1 {
+			playlist = append(playlist, "#EXTINF:4.000,", name)
+		}
+	}
+	if err := os.WriteFile(filepath.Join(dir, "index.m3u8"), []byte(strings.Join(playlist, "\n")+"\n"), 0o644); err != nil {
+		t.Fatalf("write playlist: %v", err)
+	}
+}
+
+func TestBufferedSegmentsSkipsSegmentInProgress(t *testing.T) {
+	dir := t.TempDir()
+	writeFixtureSegments(t, dir, 5)
+	segments, err := bufferedSegments(dir)
+	if err != nil {
+		t.Fatalf("bufferedSegments: %v", err)
+	}
+	if len(segments) != 4 || filepath.Base(segments[3]) != "segment_000003.ts" {
+		t.Fatalf("expected segments 0-3, got %v", segments)
+	}
+	picked := selectClipSegments(segments, 6*time.Second)
+	if len(picked) != 2 || filepath.Base(picked[0]) != "segment_000002.ts" {
+		t.Fatalf("expected the newest two segments for 6s, got %v", picked)
+	}
+	if got := selectClipSegments(segments, time.Minute); len(got) != 4 {
+		t.Fatalf("expected a long clip to use the whole buffer, got %v", got)
+	}
+}
+
+func TestJobClipConcatenatesBufferedSegments(t *testing.T) {
+	useStubFFmpeg(t)
+	tempDir := t.TempDir()
+	var exitErr atomic.Pointer[error]
+	srv, ts := startStubTranscoder(t, tempDir, &exitErr)
+	srv.launchProcess = func(id string, plan *transcodePlan, onExit func(error)) (*processState, error) {
+		done := make(chan struct{})
+		var once atomic.Bool
+		return &processState{cancel: func() {
+			if once.CompareAndSwap(false, true) {
+				close(done)
+			}
+		}, done: done}, nil
+	}
+	srv.muxClip = ffmpegClipMux
+	srv.clipBuffer = 60 * time.Second
+
+	submitJob(t, ts, "rtmp://origin/live")
+	srv.mu.RLock()
+	var meta *job
+	for _, candidate := range srv.jobs {
+		meta = candidate
+	}
+	srv.mu.RUnlock()
+	if meta == nil {
+		t.Fatal("expected a running job")
+	}
+	writeFixtureSegments(t, filepath.Dir(frameSource(meta.Renditions)), 11)
+
+	post := func(path, token, body string) *http.Response {
+		t.Helper()
+		req, err := http.NewRequest(http.MethodPost, ts.URL+path, strings.NewReader(body))
+		if err != nil {
+			t.Fatalf("build request: %v", err)
+		}
+		if token != "" {
+			req.Header.Set("Authorization", "Bearer "+token)
+		}
+		resp, err := ts.Client().Do(req)
+		if err != nil {
+			t.Fatalf("post %s: %v", path, err)
+		}
+		t.Cleanup(func() { _ = resp.Body.Close() })
+		return resp
+	}
+
+	if resp := post("/v1/jobs/"+meta.ID+"/clip", "", `{"duration":12}`); resp.StatusCode != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without token, got %d", resp.StatusCode)
+	}
+	if resp := post("/v1/jobs/unknown/clip", testToken, `{"duration":12}`); resp.StatusCode != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown job, got %d", resp.StatusCode)
+	}
+	if resp := post("/v1/jobs/"+meta.ID+"/clip", testToken, `{"duration":90}`); resp.StatusCode != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a clip longer than the buffer, got %d", resp.StatusCode)
+	}
+
+	resp := post("/v1/jobs/"+meta.ID+"/clip", testToken, `{"duration":12}`)
+	if resp.StatusCode != http.StatusCreated {
+		t.Fatalf("expected 201, got %d", resp.StatusCode)
+	}
+	var clip clipResponse
+	if err := json.NewDecoder(resp.Body).Decode(&clip); err != nil {
+		t.Fatalf("decode clip: %v", err)
+	}
+	if clip.DurationSeconds != 12 {
+		t.Fatalf("expected a 12s clip, got %d", clip.DurationSeconds)
+	}
+	if want := "https://cdn.example.com/hls/clips/" + clip.ClipID + ".mp4"; clip.PlaybackURL != want {
+		t.Fatalf("expected playback %q, got %q", want, clip.PlaybackURL)
+	}
+	data, err := os.ReadFile(filepath.Join(srv.publicRoot, "clips", clip.ClipID+".mp4"))
+	if err != nil {
+		t.Fatalf("read clip: %v", err)
+	}
+	if string(data) != "seg7;seg8;seg9;" {
+		t.Fatalf("expected the three newest finished segments in order, got %q", data)
+	}
+	entries, err := os.ReadDir(filepath.Join(srv.publicRoot, "clips"))
+	if err != nil {
+		t.Fatalf("read clips dir: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("expected the temporary clip to be renamed into place, found %d entries", len(entries))
+	}
+
+	disabled := &server{token: testToken, jobs: map[string]*job{meta.ID: meta}, muxClip: ffmpegClipMux}
+	req := httptest.NewRequest(http.MethodPost, "/v1/jobs/"+meta.ID+"/clip", strings.NewReader(`{"duration":12}`))
+	req.Header.Set("Authorization", "Bearer "+testToken)
+	rec := httptest.NewRecorder()
+	disabled.handleJobClip(rec, req, meta.ID)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 with live clips disabled, got %d", rec.Code)
+	}
+}
+
+// startPublicMirror serves a public mirror with one live job symlinked to
+// output outside the mirror, one uploaded VOD, and a secret file outside
+// both.
+func startPublicMirror(t *testing.T) (*server, *httptest.Server, string) {
+	t.Helper()
+	tempDir := t.TempDir()
+	publicDir := filepath.Join(tempDir, "public")
+	t.Setenv("BITRIVER_TRANSCODER_PUBLIC_BASE_URL", "http://transcoder:9000/public")
+	t.Setenv("BITRIVER_TRANSCODER_PUBLIC_DIR", publicDir)
+	t.Setenv("BITRIVER_TRANSCODER_SERVE_PUBLIC", "true")
+	t.Setenv("BITRIVER_TRANSCODER_PUBLIC_CORS_ORIGINS", "https://viewer.example.com")
+	srv, err := newServer(testToken, tempDir, newTestLogger(), newTestRegistry())
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+
+	liveOutput := filepath.Join(tempDir, "live", "job-1")
+	writeFile := func(path, content string) {
+		t.Helper()
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("mkdir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(content), 0o644); err != nil {
+			t.Fatalf("write %s: %v", path, err)
+		}
+	}
+	writeFile(filepath.Join(liveOutput, "index.m3u8"), "#EXTM3U\n")
+	writeFile(filepath.Join(liveOutput, "720p", "seg_0001.ts"), "0123456789abcdef")
+	writeFile(filepath.Join(liveOutput, previewFrameName), "\xff\xd8\xff\xd9")
+	writeFile(filepath.Join(publicDir, "uploads", "up-1", "720p", "init.m4s"), "fragment")
+	secret := filepath.Join(tempDir, "secret.txt")
+	writeFile(secret, "secret")
+	if err := srv.publishLive(&job{ID: "job-1", OutputPath: liveOutput}); err != nil {
+		t.Fatalf("publishLive: %v", err)
+	}
+
+	ts := httptest.NewServer(srv.routes())
+	t.Cleanup(ts.Close)
+	return srv, ts, liveOutput
+}
+
+func TestPublicMirrorServesHLSWithHeaders(t *testing.T) {
+	_, ts, _ := startPublicMirror(t)
+
+	cases := []struct {
+		path        string
+		contentType string
+		cache       string
+	}{
+		{path: "/public/live/job-1/index.m3u8", contentType: "application/vnd.apple.mpegurl", cache: playlistCacheControl},
+		{path: "/public/live/job-1/720p/seg_0001.ts", contentType: "video/mp2t", cache: segmentCacheControl},
+		{path: "/public/uploads/up-1/720p/init.m4s", contentType: "video/iso.segment", cache: segmentCacheControl},
+		{path: "/public/live/job-1/preview.jpg", contentType: "image/jpeg", cache: previewCacheControl},
+	}
+	for _, tc := range cases {
+		req, _ := http.NewRequest(http.MethodGet, ts.URL+tc.path, nil)
+		req.Header.Set("Origin", "https://viewer.example.com")
+		resp, err := http.DefaultClient.Do(req)
+		if err != nil {
+			t.Fatalf("GET %s: %v", tc.path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusOK {
+			t.Fatalf("GET %s: expected 200, got %d", tc.path, resp.StatusCode)
+		}
+		if got := resp.Header.Get("Content-Type"); got != tc.contentType {
+			t.Fatalf("GET %s: expected Content-Type %q, got %q", tc.path, tc.contentType, got)
+		}
+		if got := resp.Header.Get("Cache-Control"); got != tc.cache {
+			t.Fatalf("GET %s: expected Cache-Control %q, got %q", tc.path, tc.cache, got)
+		}
+		if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "https://viewer.example.com" {
+			t.Fatalf("GET %s: expected CORS origin, got %q", tc.path, got)
+		}
+	}
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/public/live/job-1/index.m3u8", nil)
+	req.Header.Set("Origin", "https://elsewhere.example.com")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("GET with foreign origin: %v", err)
+	}
+	resp.Body.Close()
+	if got := resp.Header.Get("Access-Control-Allow-Origin"); got != "" {
+		t.Fatalf("expected no CORS grant for a foreign origin, got %q", got)
+	}
+}
+
+func TestPublicMirrorServesRanges(t *testing.T) {
+	_, ts, _ := startPublicMirror(t)
+
+	req, _ := http.NewRequest(http.MethodGet, ts.URL+"/public/live/job-1/720p/seg_0001.ts", nil)
+	req.Header.Set("Range", "bytes=4-7")
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		t.Fatalf("range GET: %v", err)
+	}
+	defer resp.Body.Close()
+	body, _ := io.ReadAll(resp.Body)
+	if resp.StatusCode != http.StatusPartialContent {
+		t.Fatalf("expected 206, got %d", resp.StatusCode)
+	}
+	if string(body) != "4567" || resp.Header.Get("Content-Range") != "bytes 4-7/16" {
+		t.Fatalf("unexpected range response %q (%s)", body, resp.Header.Get("Content-Range"))
+	}
+}
+
+func TestPublicMirrorRejectsTraversalAndListings(t *testing.T) {
+	srv, ts, liveOutput := startPublicMirror(t)
+
+	// A symlink planted in the live output must not lead outside it.
+	if err := os.Symlink(filepath.Join(filepath.Dir(filepath.Dir(liveOutput)), "secret.txt"), filepath.Join(liveOutput, "escape.ts")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(filepath.Dir(filepath.Dir(liveOutput)), "secret.txt"), filepath.Join(srv.publicRoot, "uploads", "escape.ts")); err != nil {
+		t.Fatalf("symlink: %v", err)
+	}
+
+	for _, tc := range []struct {
+		path   string
+		status int
+	}{
+		{path: "/public/../secret.txt", status: http.StatusBadRequest},
+		{path: "/public/live/job-1/../../../secret.txt", status: http.StatusBadRequest},
+		{path: "/public/live/job-1/escape.ts", status: http.StatusNotFound},
+		{path: "/public/uploads/escape.ts", status: http.StatusNotFound},
+		{path: "/public/", status: http.StatusNotFound},
+		{path: "/public/live/job-1/", status: http.StatusNotFound},
+		{path: "/public/uploads/up-1", status: http.StatusNotFound},
+	} {
+		// Call the handler directly so the mux does not clean the path first.
+		req := httptest.NewRequest(http.MethodGet, "/", nil)
+		req.URL.Path = tc.path
+		rec := httptest.NewRecorder()
+		srv.publicFiles.ServeHTTP(rec, req)
+		if rec.Code != tc.status {
+			t.Fatalf("GET %s: expected %d, got %d", tc.path, tc.status, rec.Code)
+		}
+		if strings.Contains(rec.Body.String(), "secret") || strings.Contains(rec.Body.String(), "index.m3u8") {
+			t.Fatalf("GET %s leaked %q", tc.path, rec.Body.String())
+		}
+	}
+
+	resp, err := http.Post(ts.URL+"/public/live/job-1/index.m3u8", "text/plain", nil)
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusMethodNotAllowed {
+		t.Fatalf("expected 405 for POST, got %d", resp.StatusCode)
+	}
+}
+
+func TestPublicMirrorDisabledByDefault(t *testing.T) {
+	tempDir := t.TempDir()
+	t.Setenv("BITRIVER_TRANSCODER_PUBLIC_BASE_URL", "https://cdn.example.com/hls")
+	t.Setenv("BITRIVER_TRANSCODER_PUBLIC_DIR", filepath.Join(tempDir, "public"))
+	srv, err := newServer(testToken, tempDir, newTestLogger(), newTestRegistry())
+	if err != nil {
+		t.Fatalf("new server: %v", err)
+	}
+	if srv.publicFiles != nil {
+		t.Fatal("expected the public file server to be disabled by default")
+	}
+	rec := httptest.NewRecorder()
+	srv.routes().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/public/live/job/index.m3u8", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 when disabled, got %d", rec.Code)
+	}
+}
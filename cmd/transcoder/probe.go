@@ -0,0 +1,308 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os/exec"
+	"strconv"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/ingest"
+)
+
+const (
+	defaultProbeTimeout      = 30 * time.Second
+	defaultMaxUploadDuration = 12 * time.Hour
+	defaultMaxUploadWidth    = 3840
+	defaultMaxUploadHeight   = 2160
+
+	rejectNotVideo         = "not_video"
+	rejectTooLong          = "duration_exceeded"
+	rejectResolutionTooBig = "resolution_exceeded"
+	rejectUnreadable       = "unreadable"
+)
+
+// errProberUnavailable reports that no ffprobe binary could be located.
+// Uploads are refused unless BITRIVER_TRANSCODER_ALLOW_UNPROBED_UPLOADS
+// accepts them without validation.
+var errProberUnavailable = errors.New("ffprobe unavailable")
+
+// mediaProbe summarises the properties of an upload source discovered by
+// ffprobe before transcoding starts.
+type mediaProbe struct {
+	DurationSeconds float64 `json:"durationSeconds,omitempty"`
+	Width           int     `json:"width,omitempty"`
+	Height          int     `json:"height,omitempty"`
+	VideoCodec      string  `json:"videoCodec,omitempty"`
+	AudioCodec      string  `json:"audioCodec,omitempty"`
+	FormatName      string  `json:"formatName,omitempty"`
+	// Bitrate is the overall source bitrate in kbps, when ffprobe reports it.
+	Bitrate int `json:"bitrate,omitempty"`
+}
+
+// mediaProber inspects a source URL and reports its container and stream
+// details. Implementations return probeRejection for sources that can never
+// be transcoded and any other error for failures that may succeed on retry.
+type mediaProber interface {
+	Probe(ctx context.Context, source string) (mediaProbe, error)
+}
+
+// probeRejection marks a source that failed validation permanently. The
+// transcoder surfaces it as a 422 with a machine-readable reason.
+type probeRejection struct {
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+func (e *probeRejection) Error() string {
+	return fmt.Sprintf("%s: %s", e.Reason, e.Message)
+}
+
+// uploadLimits bounds the media accepted for upload transcodes. MaxWidth and
+// MaxHeight bound the long and short edges, so a portrait video is held to the
+// same limits as its landscape equivalent.
+type uploadLimits struct {
+	MaxDuration time.Duration
+	MaxWidth    int
+	MaxHeight   int
+	// AllowUnprobed accepts uploads without validation when ffprobe is not
+	// installed.
+	AllowUnprobed bool
+}
+
+// validate checks the probed media against the configured limits and returns
+// a probeRejection describing the first violation.
+func (l uploadLimits) validate(probe mediaProbe) error {
+	if probe.VideoCodec == "" || probe.Width <= 0 || probe.Height <= 0 {
+		return &probeRejection{Reason: rejectNotVideo, Message: "file is not a video"}
+	}
+	if l.MaxDuration > 0 && probe.DurationSeconds > l.MaxDuration.Seconds() {
+		return &probeRejection{
+			Reason:  rejectTooLong,
+			Message: fmt.Sprintf("video is longer than the maximum of %s", l.MaxDuration),
+		}
+	}
+	long, short := probe.Width, probe.Height
+	if short > long {
+		long, short = short, long
+	}
+	maxLong, maxShort := l.MaxWidth, l.MaxHeight
+	if maxLong > 0 && maxShort > maxLong {
+		maxLong, maxShort = maxShort, maxLong
+	}
+	if (maxLong > 0 && long > maxLong) || (maxShort > 0 && short > maxShort) {
+		return &probeRejection{
+			Reason:  rejectResolutionTooBig,
+			Message: fmt.Sprintf("video resolution %dx%d exceeds the maximum of %dx%d", probe.Width, probe.Height, l.MaxWidth, l.MaxHeight),
+		}
+	}
+	return nil
+}
+
+func loadUploadLimits() (uploadLimits, time.Duration, error) {
+	limits := uploadLimits{
+		MaxDuration: defaultMaxUploadDuration,
+		MaxWidth:    defaultMaxUploadWidth,
+		MaxHeight:   defaultMaxUploadHeight,
+	}
+	timeout := defaultProbeTimeout
+	if raw := envOrDefault("BITRIVER_TRANSCODER_MAX_UPLOAD_DURATION", ""); raw != "" {
+		value, err := time.ParseDuration(raw)
+		if err != nil || value < 0 {
+			return uploadLimits{}, 0, fmt.Errorf("invalid BITRIVER_TRANSCODER_MAX_UPLOAD_DURATION %q", raw)
+		}
+		limits.MaxDuration = value
+	}
+	if raw := envOrDefault("BITRIVER_TRANSCODER_MAX_UPLOAD_WIDTH", ""); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 0 {
+			return uploadLimits{}, 0, fmt.Errorf("invalid BITRIVER_TRANSCODER_MAX_UPLOAD_WIDTH %q", raw)
+		}
+		limits.MaxWidth = value
+	}
+	if raw := envOrDefault("BITRIVER_TRANSCODER_MAX_UPLOAD_HEIGHT", ""); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 0 {
+			return uploadLimits{}, 0, fmt.Errorf("invalid BITRIVER_TRANSCODER_MAX_UPLOAD_HEIGHT %q", raw)
+		}
+		limits.MaxHeight = value
+	}
+	if raw := envOrDefault("BITRIVER_TRANSCODER_PROBE_TIMEOUT", ""); raw != "" {
+		value, err := time.ParseDuration(raw)
+		if err != nil || value <= 0 {
+			return uploadLimits{}, 0, fmt.Errorf("invalid BITRIVER_TRANSCODER_PROBE_TIMEOUT %q", raw)
+		}
+		timeout = value
+	}
+	if raw := envOrDefault("BITRIVER_TRANSCODER_ALLOW_UNPROBED_UPLOADS", ""); raw != "" {
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return uploadLimits{}, 0, fmt.Errorf("invalid BITRIVER_TRANSCODER_ALLOW_UNPROBED_UPLOADS %q", raw)
+		}
+		limits.AllowUnprobed = value
+	}
+	return limits, timeout, nil
+}
+
+// ffprobeProber shells out to ffprobe and parses its JSON report.
+type ffprobeProber struct {
+	binary string
+}
+
+func newFFprobeProber() mediaProber {
+	return ffprobeProber{binary: "ffprobe"}
+}
+
+type ffprobeOutput struct {
+	Streams []struct {
+		CodecType string `json:"codec_type"`
+		CodecName string `json:"codec_name"`
+		Width     int    `json:"width"`
+		Height    int    `json:"height"`
+		Duration  string `json:"duration"`
+	} `json:"streams"`
+	Format struct {
+		FormatName string `json:"format_name"`
+		Duration   string `json:"duration"`
+		BitRate    string `json:"bit_rate"`
+	} `json:"format"`
+}
+
+func (p ffprobeProber) Probe(ctx context.Context, source string) (mediaProbe, error) {
+	binary, err := exec.LookPath(p.binary)
+	if err != nil {
+		return mediaProbe{}, errProberUnavailable
+	}
+	cmd := exec.CommandContext(ctx, binary,
+		"-v", "error",
+		"-print_format", "json",
+		"-show_format",
+		"-show_streams",
+		source,
+	)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return mediaProbe{}, fmt.Errorf("ffprobe: %w", ctxErr)
+		}
+		detail := strings.TrimSpace(stderr.String())
+		if isTransientProbeFailure(detail) {
+			return mediaProbe{}, fmt.Errorf("ffprobe: %s", detail)
+		}
+		return mediaProbe{}, &probeRejection{Reason: rejectUnreadable, Message: "file could not be read as media"}
+	}
+	return parseFFprobeOutput(stdout.Bytes())
+}
+
+func parseFFprobeOutput(data []byte) (mediaProbe, error) {
+	var output ffprobeOutput
+	if err := json.Unmarshal(data, &output); err != nil {
+		return mediaProbe{}, &probeRejection{Reason: rejectUnreadable, Message: "file could not be read as media"}
+	}
+	probe := mediaProbe{FormatName: output.Format.FormatName}
+	probe.DurationSeconds, _ = strconv.ParseFloat(strings.TrimSpace(output.Format.Duration), 64)
+	if bitsPerSecond, err := strconv.Atoi(strings.TrimSpace(output.Format.BitRate)); err == nil && bitsPerSecond > 0 {
+		probe.Bitrate = bitsPerSecond / 1000
+	}
+	for _, stream := range output.Streams {
+		switch stream.CodecType {
+		case "video":
+			if probe.VideoCodec != "" {
+				continue
+			}
+			probe.VideoCodec = stream.CodecName
+			probe.Width = stream.Width
+			probe.Height = stream.Height
+			if probe.DurationSeconds == 0 {
+				probe.DurationSeconds, _ = strconv.ParseFloat(strings.TrimSpace(stream.Duration), 64)
+			}
+		case "audio":
+			if probe.AudioCodec == "" {
+				probe.AudioCodec = stream.CodecName
+			}
+		}
+	}
+	return probe, nil
+}
+
+// isTransientProbeFailure reports whether ffprobe's stderr points at a
+// network or upstream problem rather than a broken file.
+func isTransientProbeFailure(detail string) bool {
+	lowered := strings.ToLower(detail)
+	for _, marker := range []string{
+		"connection refused",
+		"connection reset",
+		"connection timed out",
+		"timed out",
+		"network is unreachable",
+		"temporary failure in name resolution",
+		"server returned 5",
+		"i/o error",
+	} {
+		if strings.Contains(lowered, marker) {
+			return true
+		}
+	}
+	return false
+}
+
+// fitUploadLadder drops the rungs of ladder that would upscale the probed
+// source. It shares ingest.SelectUploadLadder with the API, which records the
+// skipped rungs on the upload, so both sides agree on the output.
+func fitUploadLadder(ladder []rendition, probe mediaProbe) []rendition {
+	if len(ladder) == 0 {
+		ladder = []rendition{defaultRendition}
+	}
+	requested := make([]ingest.Rendition, 0, len(ladder))
+	for _, rung := range ladder {
+		requested = append(requested, ingest.Rendition{Name: rung.Name, Bitrate: rung.Bitrate})
+	}
+	selection := ingest.SelectUploadLadder(requested, ingest.MediaInfo{
+		Width:   probe.Width,
+		Height:  probe.Height,
+		Bitrate: probe.Bitrate,
+	})
+	fitted := make([]rendition, 0, len(selection.Renditions))
+	for _, rung := range selection.Renditions {
+		fitted = append(fitted, rendition{Name: rung.Name, Bitrate: rung.Bitrate})
+	}
+	return fitted
+}
+
+// probeUpload runs the configured prober against source and validates the
+// result. A nil probe with a nil error means validation was skipped. Without
+// ffprobe the upload fails as retryable unless unprobed uploads are allowed.
+func (s *server) probeUpload(ctx context.Context, source string) (*mediaProbe, error) {
+	if s.prober == nil {
+		return nil, nil
+	}
+	timeout := s.probeTimeout
+	if timeout <= 0 {
+		timeout = defaultProbeTimeout
+	}
+	probeCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	probe, err := s.prober.Probe(probeCtx, source)
+	if err != nil {
+		if errors.Is(err, errProberUnavailable) {
+			if !s.uploadLimits.AllowUnprobed {
+				return nil, fmt.Errorf("%w; install ffprobe or set BITRIVER_TRANSCODER_ALLOW_UNPROBED_UPLOADS", err)
+			}
+			if s.logger != nil {
+				s.logger.Warn("ffprobe not found; accepting upload without validation")
+			}
+			return nil, nil
+		}
+		return nil, err
+	}
+	if err := s.uploadLimits.validate(probe); err != nil {
+		return nil, err
+	}
+	return &probe, nil
+}
@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+	"strings"
+)
+
+const (
+	publicFilesPrefix = "/public/"
+	// playlistCacheControl keeps players polling live playlists for new
+	// segments.
+	playlistCacheControl = "no-cache"
+	// segmentCacheControl lets segments, which never change once written,
+	// be cached indefinitely.
+	segmentCacheControl = "public, max-age=31536000, immutable"
+	// previewCacheControl lets CDNs hold a live preview frame only briefly,
+	// since the transcoder overwrites it on every capture.
+	previewCacheControl = "public, max-age=10"
+)
+
+var publicContentTypes = map[string]string{
+	".m3u8": "application/vnd.apple.mpegurl",
+	".ts":   "video/mp2t",
+	".m4s":  "video/iso.segment",
+	".mp4":  "video/mp4",
+	".aac":  "audio/aac",
+	".vtt":  "text/vtt; charset=utf-8",
+	".jpg":  "image/jpeg",
+}
+
+// publicFileServer serves the public mirror directly from the transcoder for
+// deployments without a separate web server. It never lists directories and
+// only serves files that resolve inside the mirror root; live job
+// directories are symlinks to job output, so files under live/{job} must
+// resolve inside that job's output instead.
+type publicFileServer struct {
+	root           string
+	allowedOrigins []string
+}
+
+// loadPublicFileServer builds the mirror file server when
+// BITRIVER_TRANSCODER_SERVE_PUBLIC is enabled and returns nil otherwise.
+// BITRIVER_TRANSCODER_PUBLIC_CORS_ORIGINS lists the origins allowed to fetch
+// from it, or "*" for any origin.
+func loadPublicFileServer(root string) (*publicFileServer, error) {
+	raw := envOrDefault("BITRIVER_TRANSCODER_SERVE_PUBLIC", "")
+	if raw == "" {
+		return nil, nil
+	}
+	enabled, err := strconv.ParseBool(raw)
+	if err != nil {
+		return nil, fmt.Errorf("invalid BITRIVER_TRANSCODER_SERVE_PUBLIC %q", raw)
+	}
+	if !enabled {
+		return nil, nil
+	}
+	resolved, err := filepath.EvalSymlinks(root)
+	if err != nil {
+		return nil, fmt.Errorf("resolve public mirror: %w", err)
+	}
+	var origins []string
+	for _, origin := range strings.Split(envOrDefault("BITRIVER_TRANSCODER_PUBLIC_CORS_ORIGINS", ""), ",") {
+		if origin = strings.TrimRight(strings.TrimSpace(origin), "/"); origin != "" {
+			origins = append(origins, origin)
+		}
+	}
+	return &publicFileServer{root: resolved, allowedOrigins: origins}, nil
+}
+
+func (p *publicFileServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	p.writeCORS(w, r)
+	switch r.Method {
+	case http.MethodGet, http.MethodHead:
+	case http.MethodOptions:
+		w.Header().Set("Allow", "GET, HEAD, OPTIONS")
+		w.WriteHeader(http.StatusNoContent)
+		return
+	default:
+		w.Header().Set("Allow", "GET, HEAD, OPTIONS")
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	rel := strings.TrimPrefix(r.URL.Path, publicFilesPrefix)
+	for _, segment := range strings.Split(rel, "/") {
+		if segment == ".." {
+			http.Error(w, "invalid path", http.StatusBadRequest)
+			return
+		}
+	}
+	rel = strings.TrimPrefix(path.Clean("/"+rel), "/")
+	if rel == "" {
+		http.NotFound(w, r)
+		return
+	}
+	resolved, ok := p.resolve(rel)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	file, err := os.Open(resolved)
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer file.Close()
+	info, err := file.Stat()
+	if err != nil || info.IsDir() {
+		http.NotFound(w, r)
+		return
+	}
+
+	ext := strings.ToLower(path.Ext(rel))
+	if contentType, ok := publicContentTypes[ext]; ok {
+		w.Header().Set("Content-Type", contentType)
+	}
+	switch {
+	case ext == ".m3u8":
+		w.Header().Set("Cache-Control", playlistCacheControl)
+	case path.Base(rel) == previewFrameName:
+		w.Header().Set("Cache-Control", previewCacheControl)
+	default:
+		w.Header().Set("Cache-Control", segmentCacheControl)
+	}
+	// ServeContent streams from the file and answers Range requests.
+	http.ServeContent(w, r, info.Name(), info.ModTime(), file)
+}
+
+// resolve maps a cleaned slash-separated path to a file on disk, following
+// symlinks, and reports false when the target is missing or escapes the
+// directory it must stay within.
+func (p *publicFileServer) resolve(rel string) (string, bool) {
+	base := p.root
+	if segments := strings.SplitN(rel, "/", 3); len(segments) == 3 && segments[0] == "live" {
+		jobDir, err := filepath.EvalSymlinks(filepath.Join(p.root, "live", segments[1]))
+		if err != nil {
+			return "", false
+		}
+		base = jobDir
+	}
+	resolved, err := filepath.EvalSymlinks(filepath.Join(p.root, filepath.FromSlash(rel)))
+	if err != nil {
+		return "", false
+	}
+	within, err := filepath.Rel(base, resolved)
+	if err != nil || within == ".." || strings.HasPrefix(within, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return resolved, true
+}
+
+func (p *publicFileServer) writeCORS(w http.ResponseWriter, r *http.Request) {
+	origin := r.Header.Get("Origin")
+	if origin == "" || len(p.allowedOrigins) == 0 {
+		return
+	}
+	w.Header().Add("Vary", "Origin")
+	allowed := ""
+	for _, candidate := range p.allowedOrigins {
+		if candidate == "*" {
+			allowed = "*"
+			break
+		}
+		if strings.EqualFold(candidate, origin) {
+			allowed = origin
+			break
+		}
+	}
+	if allowed == "" {
+		return
+	}
+	w.Header().Set("Access-Control-Allow-Origin", allowed)
+	w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, OPTIONS")
+	w.Header().Set("Access-Control-Allow-Headers", "Range")
+	w.Header().Set("Access-Control-Expose-Headers", "Content-Length, Content-Range")
+}
@@ -0,0 +1,358 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/ingest"
+)
+
+const (
+	defaultRestreamRetries = 5
+	defaultRestreamBackoff = 2 * time.Second
+	maxRestreamBackoff     = 30 * time.Second
+	// restreamStableAfter is how long a push must run before its restart
+	// budget is refilled, so a long stream survives occasional drops.
+	restreamStableAfter = time.Minute
+	// maxRestreamTargets bounds a single request. The API caps targets per
+	// channel well below this.
+	maxRestreamTargets = 16
+)
+
+// restreamTarget is one destination in a restream request.
+type restreamTarget struct {
+	ID        string `json:"id"`
+	URL       string `json:"url"`
+	StreamKey string `json:"streamKey"`
+}
+
+type restreamRequest struct {
+	ChannelID string           `json:"channelId"`
+	SessionID string           `json:"sessionId"`
+	OriginURL string           `json:"originUrl"`
+	Targets   []restreamTarget `json:"targets"`
+}
+
+type restreamResponse struct {
+	Restreams []ingest.RestreamStatus `json:"restreams"`
+}
+
+// restream supervises the ffmpeg push of a channel's origin to one target.
+// Restreams are not persisted: they belong to a live session, and the
+// controller starts them again when the stream does. All fields are guarded
+// by server.mu.
+type restream struct {
+	id         string
+	channelID  string
+	sessionID  string
+	targetID   string
+	args       []string
+	state      string
+	restarts   int
+	attempt    int
+	lastError  string
+	launchedAt time.Time
+	proc       *processState
+	retry      *time.Timer
+	stopped    bool
+}
+
+func loadRestreamPolicy() (int, time.Duration, error) {
+	retries := defaultRestreamRetries
+	if raw := envOrDefault("BITRIVER_TRANSCODER_RESTREAM_RETRIES", ""); raw != "" {
+		value, err := strconv.Atoi(raw)
+		if err != nil || value < 0 {
+			return 0, 0, fmt.Errorf("invalid BITRIVER_TRANSCODER_RESTREAM_RETRIES %q", raw)
+		}
+		retries = value
+	}
+	backoff := defaultRestreamBackoff
+	if raw := envOrDefault("BITRIVER_TRANSCODER_RESTREAM_BACKOFF", ""); raw != "" {
+		value, err := time.ParseDuration(raw)
+		if err != nil || value <= 0 {
+			return 0, 0, fmt.Errorf("invalid BITRIVER_TRANSCODER_RESTREAM_BACKOFF %q", raw)
+		}
+		backoff = value
+	}
+	return retries, backoff, nil
+}
+
+// restreamDestination joins a target's ingest URL and stream key the way
+// RTMP services expect: the key is the last path segment.
+func restreamDestination(target restreamTarget) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(target.URL))
+	if err != nil || parsed.Host == "" {
+		return "", fmt.Errorf("target %s: invalid url", target.ID)
+	}
+	if parsed.Scheme != "rtmp" && parsed.Scheme != "rtmps" {
+		return "", fmt.Errorf("target %s: url must use rtmp or rtmps", target.ID)
+	}
+	key := strings.TrimSpace(target.StreamKey)
+	if key == "" {
+		return "", fmt.Errorf("target %s: streamKey is required", target.ID)
+	}
+	return strings.TrimSuffix(parsed.String(), "/") + "/" + key, nil
+}
+
+// buildRestreamArgs copies origin to destination without re-encoding.
+func buildRestreamArgs(origin, destination string) []string {
+	return []string{
+		"-hide_banner",
+		"-loglevel", "warning",
+		"-i", origin,
+		"-c", "copy",
+		"-f", "flv",
+		destination,
+	}
+}
+
+// startRestreamFFmpeg runs ffmpeg with args for the restream id. Output is
+// logged without the arguments, which carry the target's stream key.
+func (s *server) startRestreamFFmpeg(id string, args []string, onExit func(error)) (*processState, error) {
+	ctx, cancel := context.WithCancel(context.Background())
+	cmd := exec.CommandContext(ctx, "ffmpeg", args...)
+	processLogger := s.logger
+	if processLogger != nil {
+		processLogger = processLogger.With("restream_id", id)
+	}
+	cmd.Stdout = newLogWriter(id, "stdout", processLogger)
+	cmd.Stderr = newLogWriter(id, "stderr", processLogger)
+	if err := cmd.Start(); err != nil {
+		cancel()
+		return nil, err
+	}
+	proc := &processState{cmd: cmd, cancel: cancel, done: make(chan struct{})}
+	go func() {
+		err := cmd.Wait()
+		if onExit != nil {
+			onExit(err)
+		}
+		cancel()
+		close(proc.done)
+	}()
+	return proc, nil
+}
+
+// handleRestreams starts restreams for a channel, replacing any it already
+// has. Individual targets that fail to launch are retried like targets that
+// drop later, so the request only fails when it is malformed.
+func (s *server) handleRestreams(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.authorize(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	var req restreamRequest
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid payload", http.StatusBadRequest)
+		return
+	}
+	if strings.TrimSpace(req.ChannelID) == "" || strings.TrimSpace(req.OriginURL) == "" {
+		http.Error(w, "channelId and originUrl are required", http.StatusBadRequest)
+		return
+	}
+	if len(req.Targets) > maxRestreamTargets {
+		http.Error(w, fmt.Sprintf("at most %d targets are allowed", maxRestreamTargets), http.StatusBadRequest)
+		return
+	}
+	restreams := make([]*restream, 0, len(req.Targets))
+	for _, target := range req.Targets {
+		destination, err := restreamDestination(target)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		restreams = append(restreams, &restream{
+			id:        newID("restream"),
+			channelID: req.ChannelID,
+			sessionID: req.SessionID,
+			targetID:  target.ID,
+			args:      buildRestreamArgs(req.OriginURL, destination),
+		})
+	}
+
+	s.stopRestreams(req.ChannelID)
+	s.mu.Lock()
+	for _, rs := range restreams {
+		s.restreams[rs.id] = rs
+	}
+	s.mu.Unlock()
+	for _, rs := range restreams {
+		s.launchRestreamProcess(rs)
+	}
+	if s.logger != nil {
+		s.logger.Info("restreams started", "channel_id", req.ChannelID, "session_id", req.SessionID, "targets", len(restreams))
+	}
+	s.writeJSON(w, http.StatusCreated, restreamResponse{Restreams: s.restreamStatus(req.ChannelID)})
+}
+
+// handleRestreamByChannel reports (GET) or stops (DELETE) the restreams of
+// the channel named in the path.
+func (s *server) handleRestreamByChannel(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodDelete {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+	if !s.authorize(r) {
+		http.Error(w, "unauthorized", http.StatusUnauthorized)
+		return
+	}
+	channelID := strings.TrimPrefix(r.URL.Path, "/v1/restreams/")
+	if channelID == "" || strings.Contains(channelID, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	if r.Method == http.MethodGet {
+		s.writeJSON(w, http.StatusOK, restreamResponse{Restreams: s.restreamStatus(channelID)})
+		return
+	}
+	if stopped := s.stopRestreams(channelID); stopped > 0 && s.logger != nil {
+		s.logger.Info("restreams stopped", "channel_id", channelID, "targets", stopped)
+	}
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// launchRestreamProcess starts rs's push unless it has been stopped. A
+// launch failure counts against the restart budget like an exit.
+func (s *server) launchRestreamProcess(rs *restream) {
+	s.mu.Lock()
+	if rs.stopped {
+		s.mu.Unlock()
+		return
+	}
+	rs.retry = nil
+	rs.attempt++
+	attempt := rs.attempt
+	rs.state = ingest.RestreamConnected
+	rs.launchedAt = time.Now()
+	launch := s.launchRestream
+	s.mu.Unlock()
+
+	proc, err := launch(rs.id, rs.args, func(exitErr error) {
+		s.restreamExited(rs, attempt, exitErr)
+	})
+	if err != nil {
+		s.restreamExited(rs, attempt, err)
+		return
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	switch {
+	case rs.stopped:
+		proc.cancel()
+	case rs.attempt == attempt && rs.state == ingest.RestreamConnected:
+		// The push may already have exited, in which case its exit has been
+		// handled and there is no process to track.
+		rs.proc = proc
+	}
+}
+
+// restreamExited schedules a restart of rs after an unexpected exit, or
+// marks it failed once its restart budget is spent. Exits caused by
+// stopRestreams are ignored. A failing target never touches the channel's
+// transcode job.
+func (s *server) restreamExited(rs *restream, attempt int, err error) {
+	s.mu.Lock()
+	if rs.stopped || rs.attempt != attempt || rs.state != ingest.RestreamConnected {
+		s.mu.Unlock()
+		return
+	}
+	rs.proc = nil
+	if time.Since(rs.launchedAt) >= restreamStableAfter {
+		rs.restarts = 0
+	}
+	if err == nil {
+		err = errors.New("ffmpeg exited")
+	}
+	rs.lastError = err.Error()
+	logger := s.logger
+	if logger != nil {
+		logger = logger.With("restream_id", rs.id, "channel_id", rs.channelID, "target_id", rs.targetID)
+	}
+	if rs.restarts >= s.restreamRetries {
+		rs.state = ingest.RestreamFailed
+		s.mu.Unlock()
+		if logger != nil {
+			logger.Error("restream failed", "restarts", rs.restarts, "error", err)
+		}
+		return
+	}
+	rs.restarts++
+	rs.state = ingest.RestreamRetrying
+	delay := s.restreamBackoff << (rs.restarts - 1)
+	if delay <= 0 || delay > maxRestreamBackoff {
+		delay = maxRestreamBackoff
+	}
+	rs.retry = time.AfterFunc(delay, func() { s.launchRestreamProcess(rs) })
+	s.mu.Unlock()
+	if logger != nil {
+		logger.Warn("restream exited, restarting", "restart", rs.restarts, "delay", delay, "error", err)
+	}
+}
+
+// stopRestreams stops and forgets every restream of channelID, waiting
+// briefly for each push to exit. It returns how many were stopped.
+func (s *server) stopRestreams(channelID string) int {
+	var procs []*processState
+	stopped := 0
+	s.mu.Lock()
+	for id, rs := range s.restreams {
+		if rs.channelID != channelID {
+			continue
+		}
+		rs.stopped = true
+		if rs.retry != nil {
+			rs.retry.Stop()
+		}
+		if rs.proc != nil {
+			procs = append(procs, rs.proc)
+		}
+		delete(s.restreams, id)
+		stopped++
+	}
+	s.mu.Unlock()
+	for _, proc := range procs {
+		proc.cancel()
+		select {
+		case <-proc.done:
+		case <-time.After(15 * time.Second):
+			if s.logger != nil {
+				s.logger.Warn("timeout waiting for restream to stop", "channel_id", channelID)
+			}
+		}
+	}
+	return stopped
+}
+
+// restreamStatus lists the restreams of channelID ordered by target.
+func (s *server) restreamStatus(channelID string) []ingest.RestreamStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	statuses := make([]ingest.RestreamStatus, 0)
+	for _, rs := range s.restreams {
+		if rs.channelID != channelID {
+			continue
+		}
+		statuses = append(statuses, ingest.RestreamStatus{
+			TargetID:  rs.targetID,
+			State:     rs.state,
+			Restarts:  rs.restarts,
+			LastError: rs.lastError,
+		})
+	}
+	sort.Slice(statuses, func(i, j int) bool { return statuses[i].TargetID < statuses[j].TargetID })
+	return statuses
+}
@@ -0,0 +1,149 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/ingest"
+)
+
+func TestRestreamDestination(t *testing.T) {
+	cases := []struct {
+		target restreamTarget
+		want   string
+	}{
+		{target: restreamTarget{ID: "a", URL: "rtmp://live.example.com/app", StreamKey: "key"}, want: "rtmp://live.example.com/app/key"},
+		{target: restreamTarget{ID: "b", URL: "rtmps://live.example.com:443/app/", StreamKey: " key "}, want: "rtmps://live.example.com:443/app/key"},
+		{target: restreamTarget{ID: "c", URL: "https://live.example.com/app", StreamKey: "key"}},
+		{target: restreamTarget{ID: "d", URL: "rtmp:///app", StreamKey: "key"}},
+		{target: restreamTarget{ID: "e", URL: "rtmp://live.example.com/app"}},
+	}
+	for _, tc := range cases {
+		got, err := restreamDestination(tc.target)
+		if tc.want == "" {
+			if err == nil {
+				t.Fatalf("%s: expected %q to be rejected", tc.target.ID, tc.target.URL)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Fatalf("%s: expected %q, got %q (err %v)", tc.target.ID, tc.want, got, err)
+		}
+	}
+}
+
+// TestRestreamLifecycleIsolatesFailingTargets runs restreams through the
+// ffmpeg stub: a reachable target stays connected, an unreachable one is
+// restarted until its budget is spent, and neither touches the live job.
+func TestRestreamLifecycleIsolatesFailingTargets(t *testing.T) {
+	useStubFFmpeg(t)
+	srv := newDiskTestServer(t)
+	srv.disk = diskLimits{}
+	srv.launchRestream = srv.startRestreamFFmpeg
+	srv.restreamRetries = 2
+	srv.restreamBackoff = 10 * time.Millisecond
+
+	send := func(method, path string, body any) *httptest.ResponseRecorder {
+		t.Helper()
+		var payload []byte
+		if body != nil {
+			var err error
+			if payload, err = json.Marshal(body); err != nil {
+				t.Fatalf("marshal request: %v", err)
+			}
+		}
+		req := httptest.NewRequest(method, path, bytes.NewReader(payload))
+		req.Header.Set("Authorization", "Bearer "+testToken)
+		res := httptest.NewRecorder()
+		srv.routes().ServeHTTP(res, req)
+		return res
+	}
+	status := func() map[string]ingest.RestreamStatus {
+		t.Helper()
+		res := send(http.MethodGet, "/v1/restreams/channel-1", nil)
+		if res.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", res.Code, res.Body.String())
+		}
+		var decoded restreamResponse
+		if err := json.Unmarshal(res.Body.Bytes(), &decoded); err != nil {
+			t.Fatalf("decode status: %v", err)
+		}
+		byTarget := make(map[string]ingest.RestreamStatus, len(decoded.Restreams))
+		for _, entry := range decoded.Restreams {
+			byTarget[entry.TargetID] = entry
+		}
+		return byTarget
+	}
+
+	res := send(http.MethodPost, "/v1/jobs", map[string]any{
+		"channelId": "channel-1", "sessionId": "session-1", "originUrl": "https://origin/live.m3u8",
+		"renditions": []map[string]any{{"name": "720p", "bitrate": 2000}},
+	})
+	if res.Code != http.StatusCreated {
+		t.Fatalf("expected live job, got %d: %s", res.Code, res.Body.String())
+	}
+
+	if res := send(http.MethodPost, "/v1/restreams", map[string]any{"channelId": "channel-1", "originUrl": "https://origin/live.m3u8", "targets": []map[string]any{
+		{"id": "bad", "url": "ftp://live.example.com/app", "streamKey": "key"},
+	}}); res.Code != http.StatusBadRequest {
+		t.Fatalf("expected an invalid target rejected, got %d", res.Code)
+	}
+	res = send(http.MethodPost, "/v1/restreams", map[string]any{
+		"channelId": "channel-1", "sessionId": "session-1", "originUrl": "https://origin/live.m3u8",
+		"targets": []map[string]any{
+			{"id": "good", "url": "rtmp://live.example.com/app", "streamKey": "key-1"},
+			{"id": "down", "url": "rtmp://unreachable.example.com/app", "streamKey": "key-2"},
+		},
+	})
+	if res.Code != http.StatusCreated {
+		t.Fatalf("expected restreams started, got %d: %s", res.Code, res.Body.String())
+	}
+
+	deadline := time.Now().Add(5 * time.Second)
+	for status()["down"].State != ingest.RestreamFailed {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the unreachable target to fail, got %+v", status())
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	current := status()
+	if down := current["down"]; down.Restarts != 2 || down.LastError == "" {
+		t.Fatalf("expected the failed target to use its restart budget, got %+v", down)
+	}
+	if good := current["good"]; good.State != ingest.RestreamConnected || good.Restarts != 0 {
+		t.Fatalf("expected the reachable target to stay connected, got %+v", good)
+	}
+	srv.mu.RLock()
+	jobs := len(srv.jobs)
+	srv.mu.RUnlock()
+	if jobs != 1 {
+		t.Fatalf("expected the live job untouched, got %d jobs", jobs)
+	}
+
+	srv.mu.RLock()
+	var pushes []*processState
+	for _, rs := range srv.restreams {
+		if rs.proc != nil {
+			pushes = append(pushes, rs.proc)
+		}
+	}
+	srv.mu.RUnlock()
+	if len(pushes) != 1 {
+		t.Fatalf("expected one running push, got %d", len(pushes))
+	}
+	if res := send(http.MethodDelete, "/v1/restreams/channel-1", nil); res.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", res.Code)
+	}
+	select {
+	case <-pushes[0].done:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the push to be stopped")
+	}
+	if remaining := status(); len(remaining) != 0 {
+		t.Fatalf("expected no restreams after stopping, got %+v", remaining)
+	}
+}
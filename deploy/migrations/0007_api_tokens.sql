@@ -0,0 +1,20 @@
+-- 0007_api_tokens.sql
+--
+-- Stores personal access tokens used by automation tools. Only a SHA-256 hash
+-- of each secret is kept; the plaintext is shown to the user once.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS api_tokens (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    name TEXT NOT NULL,
+    scopes TEXT[] NOT NULL DEFAULT '{}',
+    token_hash TEXT NOT NULL UNIQUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ
+);
+
+CREATE INDEX IF NOT EXISTS api_tokens_user_idx ON api_tokens (user_id);
+
+COMMIT;
@@ -0,0 +1,14 @@
+-- 0008_subscription_gifts.sql
+--
+-- Records who gifted a subscription so gifted rows can be credited to the
+-- viewer that paid for them.
+
+BEGIN;
+
+ALTER TABLE subscriptions
+    ADD COLUMN IF NOT EXISTS gifter_id TEXT REFERENCES users(id) ON DELETE SET NULL,
+    ADD COLUMN IF NOT EXISTS is_gift BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE INDEX IF NOT EXISTS subscriptions_gifter_idx ON subscriptions (gifter_id) WHERE gifter_id IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,11 @@
+-- 0009_chat_messages_created_at_index.sql
+--
+-- Lets recording chat replay read a channel's messages by time window
+-- without scanning the whole transcript.
+
+BEGIN;
+
+CREATE INDEX IF NOT EXISTS chat_messages_channel_created_at_idx
+    ON chat_messages (channel_id, created_at, id);
+
+COMMIT;
@@ -0,0 +1,29 @@
+-- 0010_channel_stream_key_hashes.sql
+--
+-- Replaces plaintext channel stream keys with a SHA-256 digest and a short
+-- display hint. Existing keys are hashed in place so encoders keep working;
+-- the plaintext column is dropped afterwards.
+
+BEGIN;
+
+CREATE EXTENSION IF NOT EXISTS pgcrypto;
+
+ALTER TABLE channels
+    ADD COLUMN IF NOT EXISTS stream_key_hash TEXT,
+    ADD COLUMN IF NOT EXISTS stream_key_hint TEXT NOT NULL DEFAULT '';
+
+UPDATE channels
+SET stream_key_hash = encode(digest(btrim(stream_key), 'sha256'), 'hex'),
+    stream_key_hint = CASE
+        WHEN length(btrim(stream_key)) > 8 THEN left(btrim(stream_key), 4) || '…' || right(btrim(stream_key), 4)
+        ELSE repeat('*', length(btrim(stream_key)))
+    END
+WHERE stream_key_hash IS NULL;
+
+ALTER TABLE channels ALTER COLUMN stream_key_hash SET NOT NULL;
+
+CREATE UNIQUE INDEX IF NOT EXISTS channels_stream_key_hash_idx ON channels (stream_key_hash);
+
+ALTER TABLE channels DROP COLUMN IF EXISTS stream_key;
+
+COMMIT;
@@ -0,0 +1,18 @@
+-- 0011_channel_editors.sql
+--
+-- Per-channel grants that let users with the editor role manage a channel's
+-- recordings and uploads without owning it.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS channel_editors (
+    channel_id TEXT NOT NULL REFERENCES channels(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    granted_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    granted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (channel_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS channel_editors_user_idx ON channel_editors (user_id);
+
+COMMIT;
@@ -0,0 +1,30 @@
+-- 0012_user_listing_indexes.sql
+--
+-- Supports the paginated admin user and profile listings: trigram indexes
+-- serve case-insensitive substring search on email and display name, the
+-- roles GIN index serves role filters, and the expression indexes match the
+-- ORDER BY used for each sort key.
+
+BEGIN;
+
+CREATE EXTENSION IF NOT EXISTS pg_trgm;
+
+CREATE INDEX IF NOT EXISTS users_email_trgm_idx
+    ON users USING GIN (email gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS users_display_name_trgm_idx
+    ON users USING GIN (display_name gin_trgm_ops);
+
+CREATE INDEX IF NOT EXISTS users_roles_idx
+    ON users USING GIN (roles);
+
+CREATE INDEX IF NOT EXISTS users_created_at_idx
+    ON users (created_at, id);
+
+CREATE INDEX IF NOT EXISTS users_display_name_sort_idx
+    ON users ((LOWER(display_name) COLLATE "C"), id);
+
+CREATE INDEX IF NOT EXISTS users_email_sort_idx
+    ON users ((LOWER(email) COLLATE "C"), id);
+
+COMMIT;
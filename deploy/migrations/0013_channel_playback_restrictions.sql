@@ -0,0 +1,20 @@
+-- 0013_channel_playback_restrictions.sql
+--
+-- Lets creators restrict live playback to followers or subscribers. The API
+-- only hands signed manifest URLs to qualifying viewers; playback_previews
+-- additionally issues anonymous tokens to everyone else.
+
+BEGIN;
+
+ALTER TABLE channels
+    ADD COLUMN IF NOT EXISTS playback_restriction TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS playback_previews BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE channels
+    DROP CONSTRAINT IF EXISTS channels_playback_restriction_check;
+
+ALTER TABLE channels
+    ADD CONSTRAINT channels_playback_restriction_check
+    CHECK (playback_restriction IN ('', 'followers', 'subscribers'));
+
+COMMIT;
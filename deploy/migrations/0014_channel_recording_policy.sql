@@ -0,0 +1,19 @@
+-- 0014_channel_recording_policy.sql
+--
+-- Lets creators choose what happens to a stream's recording when it ends:
+-- keep it unpublished (manual), publish it straight away (auto-publish), or
+-- skip recording altogether (discard).
+
+BEGIN;
+
+ALTER TABLE channels
+    ADD COLUMN IF NOT EXISTS recording_policy TEXT NOT NULL DEFAULT 'manual';
+
+ALTER TABLE channels
+    DROP CONSTRAINT IF EXISTS channels_recording_policy_check;
+
+ALTER TABLE channels
+    ADD CONSTRAINT channels_recording_policy_check
+    CHECK (recording_policy IN ('manual', 'auto-publish', 'discard'));
+
+COMMIT;
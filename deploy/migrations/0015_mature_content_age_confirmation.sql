@@ -0,0 +1,15 @@
+-- 0015_mature_content_age_confirmation.sql
+--
+-- Adds the mature content flag to channels and the one-time birth date
+-- confirmation viewers provide before watching mature channels.
+
+BEGIN;
+
+ALTER TABLE channels
+    ADD COLUMN IF NOT EXISTS mature_content BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS birth_date DATE,
+    ADD COLUMN IF NOT EXISTS age_confirmed_at TIMESTAMPTZ;
+
+COMMIT;
@@ -0,0 +1,29 @@
+-- 0016_jobs.sql
+--
+-- Adds the persistent background job queue. Completed jobs are deleted;
+-- failed jobs are kept for inspection. dedupe_key is unique among jobs that
+-- are still pending or running so recurring work is never queued twice.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS jobs (
+    id TEXT PRIMARY KEY,
+    type TEXT NOT NULL,
+    dedupe_key TEXT,
+    payload JSONB NOT NULL DEFAULT '{}'::jsonb,
+    status TEXT NOT NULL DEFAULT 'pending' CHECK (status IN ('pending', 'running', 'failed')),
+    run_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    attempts INTEGER NOT NULL DEFAULT 0,
+    last_error TEXT,
+    locked_by TEXT,
+    locked_until TIMESTAMPTZ,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS jobs_due_idx ON jobs (status, run_at);
+
+CREATE UNIQUE INDEX IF NOT EXISTS jobs_active_dedupe_key_idx ON jobs (dedupe_key)
+    WHERE dedupe_key IS NOT NULL AND status IN ('pending', 'running');
+
+COMMIT;
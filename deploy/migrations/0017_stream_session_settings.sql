@@ -0,0 +1,8 @@
+-- 0017_stream_session_settings.sql
+--
+-- Stores an immutable snapshot of the settings each stream session started
+-- with: the renditions the creator requested, the ladder the ingest backend
+-- built, and the latency mode. Sessions created before this migration have
+-- no snapshot.
+
+ALTER TABLE stream_sessions ADD COLUMN IF NOT EXISTS settings JSONB;
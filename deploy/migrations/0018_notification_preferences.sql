@@ -0,0 +1,17 @@
+-- 0018_notification_preferences.sql
+--
+-- Stores per-user notification preferences. Users without a row use the
+-- application defaults, so rows only exist for users who changed a setting.
+
+CREATE TABLE IF NOT EXISTS notification_preferences (
+    user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    followed_live_in_app BOOLEAN NOT NULL,
+    followed_live_email BOOLEAN NOT NULL,
+    mentions_in_app BOOLEAN NOT NULL,
+    mentions_email BOOLEAN NOT NULL,
+    moderation_in_app BOOLEAN NOT NULL,
+    moderation_email BOOLEAN NOT NULL,
+    account_in_app BOOLEAN NOT NULL,
+    account_email BOOLEAN NOT NULL,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,7 @@
+-- 0019_channel_transcode_limits.sql
+--
+-- Channels may override the platform transcode caps (maximum input height,
+-- summed output bitrate and rendition count). NULL means the channel follows
+-- the platform defaults configured on the ingest controller.
+
+ALTER TABLE channels ADD COLUMN IF NOT EXISTS transcode_limits JSONB;
@@ -0,0 +1,11 @@
+-- 0020_live_clips.sql
+--
+-- Viewers can clip a live stream before its recording exists. Such clips are
+-- stored against the stream session with a NULL recording_id, which StopStream
+-- fills in once the recording is created. created_by attributes the clip to
+-- the viewer who made it.
+
+ALTER TABLE clip_exports ALTER COLUMN recording_id DROP NOT NULL;
+ALTER TABLE clip_exports ADD COLUMN IF NOT EXISTS created_by TEXT REFERENCES users(id) ON DELETE SET NULL;
+
+CREATE INDEX IF NOT EXISTS clip_exports_unlinked_session_idx ON clip_exports (session_id) WHERE recording_id IS NULL;
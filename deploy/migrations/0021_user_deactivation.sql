@@ -0,0 +1,7 @@
+-- 0021_user_deactivation.sql
+--
+-- Users provisioned by an identity provider are deactivated rather than
+-- deleted when they are deprovisioned. deactivated_at records when; a
+-- deactivated user keeps their data but can no longer sign in.
+
+ALTER TABLE users ADD COLUMN IF NOT EXISTS deactivated_at TIMESTAMPTZ;
@@ -0,0 +1,17 @@
+-- 0022_stream_markers.sql
+--
+-- Stores the moments creators and moderators mark during a live session.
+-- offset_seconds counts from the session start; markers go with their
+-- session, and markers from deleted users keep a NULL created_by.
+
+CREATE TABLE IF NOT EXISTS stream_markers (
+    id TEXT PRIMARY KEY,
+    channel_id TEXT NOT NULL REFERENCES channels(id) ON DELETE CASCADE,
+    session_id TEXT NOT NULL REFERENCES stream_sessions(id) ON DELETE CASCADE,
+    label TEXT NOT NULL,
+    offset_seconds INTEGER NOT NULL CHECK (offset_seconds >= 0),
+    created_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS stream_markers_session_idx ON stream_markers (session_id, offset_seconds);
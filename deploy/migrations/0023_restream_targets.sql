@@ -0,0 +1,18 @@
+-- 0023_restream_targets.sql
+--
+-- Stores the external RTMP destinations a channel pushes its live stream
+-- to. Stream keys are sealed with the server secret key before they reach
+-- this table; stream_key_ciphertext never holds a plaintext key.
+
+CREATE TABLE IF NOT EXISTS restream_targets (
+    id TEXT PRIMARY KEY,
+    channel_id TEXT NOT NULL REFERENCES channels(id) ON DELETE CASCADE,
+    label TEXT NOT NULL,
+    url TEXT NOT NULL,
+    stream_key_ciphertext TEXT NOT NULL,
+    enabled BOOLEAN NOT NULL DEFAULT TRUE,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS restream_targets_channel_idx ON restream_targets (channel_id, created_at);
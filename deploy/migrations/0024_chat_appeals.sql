@@ -0,0 +1,21 @@
+-- 0024_chat_appeals.sql
+--
+-- Stores appeals from viewers banned or timed out of a channel's chat. The
+-- partial unique index allows a single open appeal per viewer and channel;
+-- resolved appeals are kept as history.
+
+CREATE TABLE IF NOT EXISTS chat_appeals (
+    id TEXT PRIMARY KEY,
+    channel_id TEXT NOT NULL REFERENCES channels(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    restriction TEXT NOT NULL,
+    message TEXT NOT NULL,
+    status TEXT NOT NULL DEFAULT 'open',
+    resolver_id TEXT REFERENCES users(id) ON DELETE SET NULL,
+    resolution_note TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    resolved_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS chat_appeals_open_idx ON chat_appeals (channel_id, user_id) WHERE status = 'open';
+CREATE INDEX IF NOT EXISTS chat_appeals_channel_idx ON chat_appeals (channel_id, status, created_at DESC);
@@ -0,0 +1,9 @@
+-- 0025_channel_starting_since.sql
+--
+-- Records when a channel entered the starting state so a second start can be
+-- refused while the first is still booting, and a start that never finished
+-- can be recovered. Channels already starting take their last update time.
+
+ALTER TABLE channels ADD COLUMN IF NOT EXISTS starting_since TIMESTAMPTZ;
+
+UPDATE channels SET starting_since = updated_at WHERE live_state = 'starting' AND starting_since IS NULL;
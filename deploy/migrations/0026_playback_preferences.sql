@@ -0,0 +1,14 @@
+-- 0026_playback_preferences.sql
+--
+-- Stores per-user playback preferences: the default rendition quality and
+-- player start-up behaviour. Users without a row use the application
+-- defaults, so rows only exist for users who changed a setting.
+
+CREATE TABLE IF NOT EXISTS playback_preferences (
+    user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    default_quality TEXT NOT NULL DEFAULT 'auto'
+        CHECK (default_quality IN ('auto', 'source', 'high', 'medium', 'low')),
+    prefer_low_latency BOOLEAN NOT NULL DEFAULT FALSE,
+    muted_autoplay BOOLEAN NOT NULL DEFAULT FALSE,
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
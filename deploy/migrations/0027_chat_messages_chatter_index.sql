@@ -0,0 +1,12 @@
+-- 0027_chat_messages_chatter_index.sql
+--
+-- Lets the chat gateway check whether a user has chatted in a channel
+-- before, and count a channel's distinct and first-time chatters, without
+-- scanning the channel's transcript.
+
+BEGIN;
+
+CREATE INDEX IF NOT EXISTS chat_messages_channel_user_created_at_idx
+    ON chat_messages (channel_id, user_id, created_at);
+
+COMMIT;
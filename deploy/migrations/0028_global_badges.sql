@@ -0,0 +1,30 @@
+-- 0028_global_badges.sql
+--
+-- Global chat badges such as staff and verified. badge_definitions holds the
+-- badges administrators can grant, so new badges are data rather than code;
+-- user_badges records who holds which badge. A definition cannot be deleted
+-- while it is still granted.
+
+CREATE TABLE IF NOT EXISTS badge_definitions (
+    slug TEXT PRIMARY KEY,
+    label TEXT NOT NULL,
+    icon_url TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE TABLE IF NOT EXISTS user_badges (
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    badge_slug TEXT NOT NULL REFERENCES badge_definitions(slug) ON DELETE RESTRICT,
+    granted_by TEXT NOT NULL DEFAULT '',
+    granted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (user_id, badge_slug)
+);
+
+CREATE INDEX IF NOT EXISTS user_badges_slug_idx ON user_badges (badge_slug, granted_at);
+
+INSERT INTO badge_definitions (slug, label) VALUES
+    ('staff', 'Staff'),
+    ('verified', 'Verified'),
+    ('founder', 'Founder')
+ON CONFLICT (slug) DO NOTHING;
@@ -0,0 +1,13 @@
+-- 0029_snapshot_import_progress.sql
+--
+-- Records which steps of a JSON snapshot import have finished, so
+-- migrate-json-to-postgres can resume after a failure. input_digest hashes
+-- the step's slice of the snapshot; a step is skipped only when the same
+-- input was imported before without failed rows.
+
+CREATE TABLE IF NOT EXISTS snapshot_import_progress (
+    step TEXT NOT NULL,
+    input_digest TEXT NOT NULL,
+    completed_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    PRIMARY KEY (step, input_digest)
+);
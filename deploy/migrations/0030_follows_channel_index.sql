@@ -0,0 +1,7 @@
+-- 0030_follows_channel_index.sql
+--
+-- Channel recommendations find the viewers who follow a channel and then
+-- everything else those viewers follow. The primary key covers the second
+-- lookup; this index covers the first.
+
+CREATE INDEX IF NOT EXISTS follows_channel_idx ON follows (channel_id, user_id);
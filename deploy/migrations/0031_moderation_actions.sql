@@ -0,0 +1,20 @@
+-- 0031_moderation_actions.sql
+--
+-- Append-only log of channel moderation: bans, unbans, timeouts, deleted
+-- messages, and resolved reports. chat_bans and chat_timeouts only hold the
+-- restrictions in force, so this is what moderators read to see a viewer's
+-- history. Entries reuse the chat event ID where there is one, which keeps a
+-- redelivered event from logging twice.
+
+CREATE TABLE IF NOT EXISTS moderation_actions (
+    id TEXT PRIMARY KEY,
+    channel_id TEXT NOT NULL REFERENCES channels(id) ON DELETE CASCADE,
+    target_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    actor_id TEXT REFERENCES users(id) ON DELETE SET NULL,
+    action TEXT NOT NULL CHECK (action IN ('ban', 'unban', 'timeout', 'remove_timeout', 'delete_message', 'resolve_report')),
+    reason TEXT NOT NULL DEFAULT '',
+    metadata JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS moderation_actions_target_idx ON moderation_actions (channel_id, target_id, created_at DESC, id DESC);
@@ -0,0 +1,7 @@
+-- 0032_stream_session_preview_url.sql
+--
+-- Records where the transcoder mirrors each live session's preview frame so
+-- directory cards can show a recent still instead of static channel art.
+-- Sessions started before this migration have no preview.
+
+ALTER TABLE stream_sessions ADD COLUMN IF NOT EXISTS preview_url TEXT NOT NULL DEFAULT '';
@@ -0,0 +1,17 @@
+-- 0033_chat_message_client_ids.sql
+--
+-- Stores the optional ID chat clients generate for each message so retried
+-- submissions can be matched to the original. The partial unique index
+-- rejects a second message with the same ID from the same user in the same
+-- channel; the application clears the ID from messages older than the dedupe
+-- window so clients may eventually reuse it.
+
+BEGIN;
+
+ALTER TABLE chat_messages ADD COLUMN IF NOT EXISTS client_message_id TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS chat_messages_client_message_id_idx
+    ON chat_messages (channel_id, user_id, client_message_id)
+    WHERE client_message_id IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,15 @@
+-- 0034_auth_session_impersonation.sql
+--
+-- Records the administrator behind an impersonation session. Sessions the
+-- user signed in to themselves leave the column NULL; the index lets revoking
+-- an administrator also end the impersonation sessions they started.
+
+BEGIN;
+
+ALTER TABLE auth_sessions ADD COLUMN IF NOT EXISTS impersonator_id TEXT;
+
+CREATE INDEX IF NOT EXISTS auth_sessions_impersonator_id_idx
+    ON auth_sessions (impersonator_id)
+    WHERE impersonator_id IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,15 @@
+-- 0035_upload_content_hash.sql
+--
+-- Records the SHA-256 of each upload's source media so a re-upload of a file
+-- the channel has already processed can reuse the earlier output instead of
+-- transcoding it again. Lookups are always scoped to a single channel.
+
+BEGIN;
+
+ALTER TABLE uploads ADD COLUMN IF NOT EXISTS content_hash TEXT;
+
+CREATE INDEX IF NOT EXISTS uploads_content_hash_idx
+    ON uploads (channel_id, content_hash)
+    WHERE content_hash IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,21 @@
+-- 0036_channel_chat_content_settings.sql
+--
+-- Per-channel chat content settings. chat_max_caps_percent lowercases
+-- messages that are more than that percentage capitals (0 turns the rule
+-- off); chat_restrict_links only accepts messages with links from followers,
+-- subscribers, and moderators.
+
+BEGIN;
+
+ALTER TABLE channels
+    ADD COLUMN IF NOT EXISTS chat_max_caps_percent INTEGER NOT NULL DEFAULT 0,
+    ADD COLUMN IF NOT EXISTS chat_restrict_links BOOLEAN NOT NULL DEFAULT FALSE;
+
+ALTER TABLE channels
+    DROP CONSTRAINT IF EXISTS channels_chat_max_caps_percent_check;
+
+ALTER TABLE channels
+    ADD CONSTRAINT channels_chat_max_caps_percent_check
+    CHECK (chat_max_caps_percent BETWEEN 0 AND 100);
+
+COMMIT;
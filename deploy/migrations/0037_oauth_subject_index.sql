@@ -0,0 +1,16 @@
+-- 0037_oauth_subject_index.sql
+--
+-- OAuth subjects are sealed like the account email once an encryption key is
+-- configured. subject_index holds a keyed digest of provider and subject so
+-- sign-in can still find the account; rows written without a key leave it
+-- NULL and keep the plaintext subject until the rotation tool seals them.
+
+BEGIN;
+
+ALTER TABLE oauth_accounts ADD COLUMN IF NOT EXISTS subject_index TEXT;
+
+CREATE UNIQUE INDEX IF NOT EXISTS oauth_accounts_subject_index_idx
+    ON oauth_accounts (provider, subject_index)
+    WHERE subject_index IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,28 @@
+-- 0038_channel_schedules.sql
+--
+-- Stores the streams a channel announces ahead of time and the tokens that
+-- let calendar apps fetch the schedule of the channels a user follows.
+-- Weekly entries repeat at the wall-clock time of starts_at in time_zone.
+
+CREATE TABLE IF NOT EXISTS schedule_entries (
+    id TEXT PRIMARY KEY,
+    channel_id TEXT NOT NULL REFERENCES channels(id) ON DELETE CASCADE,
+    title TEXT NOT NULL,
+    category TEXT NOT NULL DEFAULT '',
+    starts_at TIMESTAMPTZ NOT NULL,
+    duration_minutes INTEGER NOT NULL,
+    time_zone TEXT NOT NULL DEFAULT 'UTC',
+    recurrence TEXT NOT NULL DEFAULT '',
+    repeat_until TIMESTAMPTZ,
+    sequence INTEGER NOT NULL DEFAULT 0,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS schedule_entries_channel_idx ON schedule_entries (channel_id, starts_at);
+
+CREATE TABLE IF NOT EXISTS schedule_feed_tokens (
+    user_id TEXT PRIMARY KEY REFERENCES users(id) ON DELETE CASCADE,
+    nonce TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
@@ -0,0 +1,24 @@
+-- 0039_channel_storage.sql
+--
+-- Tracks how much VOD storage each channel uses so a per-channel quota can
+-- be enforced. used_bytes is the running total of the channel's recordings
+-- and uploads, updated in the same transaction that creates or deletes
+-- them. quota_bytes overrides the platform default: NULL uses the default
+-- and 0 lifts the quota.
+
+ALTER TABLE recordings ADD COLUMN IF NOT EXISTS size_bytes BIGINT NOT NULL DEFAULT 0;
+ALTER TABLE recordings ADD COLUMN IF NOT EXISTS over_quota BOOLEAN NOT NULL DEFAULT FALSE;
+ALTER TABLE uploads ADD COLUMN IF NOT EXISTS output_bytes BIGINT NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS channel_storage (
+    channel_id TEXT PRIMARY KEY REFERENCES channels(id) ON DELETE CASCADE,
+    used_bytes BIGINT NOT NULL DEFAULT 0 CHECK (used_bytes >= 0),
+    quota_bytes BIGINT CHECK (quota_bytes IS NULL OR quota_bytes >= 0),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+-- Existing uploads count their source size; existing recordings were never
+-- measured and count as empty.
+INSERT INTO channel_storage (channel_id, used_bytes)
+SELECT channel_id, SUM(size_bytes) FROM uploads GROUP BY channel_id
+ON CONFLICT (channel_id) DO NOTHING;
@@ -0,0 +1,14 @@
+-- 0040_channel_chat_subscribers_only.sql
+--
+-- Subscriber-only chat. Channels with chat_subscribers_only accept chat
+-- messages only from users holding an unexpired subscription to the
+-- channel, and from the people who moderate it. Subscriber months are
+-- computed from the subscriptions table through
+-- subscriptions_user_channel_idx, so no other column is needed.
+
+BEGIN;
+
+ALTER TABLE channels
+    ADD COLUMN IF NOT EXISTS chat_subscribers_only BOOLEAN NOT NULL DEFAULT FALSE;
+
+COMMIT;
@@ -0,0 +1,23 @@
+-- 0041_chat_pins_and_welcome_message.sql
+--
+-- Chat welcome messages and pinned chat messages. chat_welcome_message is
+-- shown by clients to each viewer joining a channel's chat. chat_pins holds
+-- the single message pinned above a channel's chat; pinning an existing
+-- message copies its content, so message_id is kept for reference only and
+-- the pin outlives the message.
+
+BEGIN;
+
+ALTER TABLE channels
+    ADD COLUMN IF NOT EXISTS chat_welcome_message TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS chat_pins (
+    channel_id TEXT PRIMARY KEY REFERENCES channels(id) ON DELETE CASCADE,
+    message_id TEXT,
+    author_id TEXT REFERENCES users(id) ON DELETE SET NULL,
+    content TEXT NOT NULL,
+    pinned_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    pinned_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+COMMIT;
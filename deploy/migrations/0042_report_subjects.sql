@@ -0,0 +1,26 @@
+-- 0042_report_subjects.sql
+--
+-- Reports cover live sessions, recordings, and whole channels as well as
+-- chat messages. subject_type names what was reported and subject_id its ID;
+-- it is not a foreign key so reports outlive their subjects. Reports filed
+-- before this migration are chat message reports in the "other" category.
+-- Copyright reports also record the claimant's contact details.
+
+BEGIN;
+
+ALTER TABLE chat_reports
+    ADD COLUMN IF NOT EXISTS subject_type TEXT NOT NULL DEFAULT 'chat_message',
+    ADD COLUMN IF NOT EXISTS subject_id TEXT,
+    ADD COLUMN IF NOT EXISTS category TEXT NOT NULL DEFAULT 'other',
+    ADD COLUMN IF NOT EXISTS claimant_name TEXT,
+    ADD COLUMN IF NOT EXISTS claimant_email TEXT,
+    ADD COLUMN IF NOT EXISTS claimant_phone TEXT,
+    ADD COLUMN IF NOT EXISTS claimant_address TEXT;
+
+UPDATE chat_reports
+SET subject_id = message_id
+WHERE subject_type = 'chat_message' AND subject_id IS NULL;
+
+CREATE INDEX IF NOT EXISTS chat_reports_queue_idx ON chat_reports (status, subject_type, category, created_at DESC);
+
+COMMIT;
@@ -0,0 +1,31 @@
+-- 0043_platform_stats.sql
+--
+-- Platform statistics rollups. A background job stores one row per UTC day
+-- and overwrites it on every run that day, so the newest row holds the
+-- latest aggregates. The created_at index on chat_messages lets the rollup
+-- count the day's messages without scanning the whole chat history.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS platform_stats (
+    day DATE PRIMARY KEY,
+    total_channels INTEGER NOT NULL DEFAULT 0,
+    live_channels INTEGER NOT NULL DEFAULT 0,
+    registered_users INTEGER NOT NULL DEFAULT 0,
+    new_users_today INTEGER NOT NULL DEFAULT 0,
+    sessions_started_today INTEGER NOT NULL DEFAULT 0,
+    streamed_seconds_month BIGINT NOT NULL DEFAULT 0,
+    chat_messages_today INTEGER NOT NULL DEFAULT 0,
+    computed_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS platform_stats_computed_at_idx
+    ON platform_stats (computed_at DESC);
+
+CREATE INDEX IF NOT EXISTS chat_messages_created_at_idx
+    ON chat_messages (created_at);
+
+CREATE INDEX IF NOT EXISTS stream_sessions_started_at_idx
+    ON stream_sessions (started_at);
+
+COMMIT;
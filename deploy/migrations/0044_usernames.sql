@@ -0,0 +1,50 @@
+-- 0044_usernames.sql
+--
+-- Unique usernames for mentions and profile URLs. Usernames keep the case
+-- they were chosen with and are unique ignoring case. Existing accounts get a
+-- username derived from their display name, else their email's local part,
+-- else "user", with a numeric suffix on collisions or reserved words.
+-- username_grace lets them replace it once without waiting out the change
+-- cooldown. The rules match deriveUsername in internal/storage/usernames.go.
+
+BEGIN;
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS username TEXT,
+    ADD COLUMN IF NOT EXISTS username_changed_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS username_grace BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE UNIQUE INDEX IF NOT EXISTS users_username_lower_idx ON users (lower(username));
+
+DO $$
+DECLARE
+    account RECORD;
+    base TEXT;
+    candidate TEXT;
+    suffix INTEGER;
+    reserved TEXT[] := ARRAY['about', 'admin', 'api', 'bitriver', 'directory', 'embed', 'help', 'login', 'logout', 'me', 'moderator', 'null', 'root', 'settings', 'signup', 'staff', 'support', 'system', 'undefined'];
+BEGIN
+    FOR account IN SELECT id, display_name, email FROM users WHERE username IS NULL ORDER BY created_at, id LOOP
+        base := btrim(regexp_replace(lower(account.display_name), '[^a-z0-9_]+', '_', 'g'), '_');
+        base := rtrim(left(base, 25), '_');
+        IF length(base) < 3 THEN
+            base := btrim(regexp_replace(lower(split_part(account.email, '@', 1)), '[^a-z0-9_]+', '_', 'g'), '_');
+            base := rtrim(left(base, 25), '_');
+        END IF;
+        IF length(base) < 3 THEN
+            base := 'user';
+        END IF;
+        candidate := base;
+        suffix := 1;
+        WHILE candidate = ANY (reserved) OR EXISTS (SELECT 1 FROM users WHERE lower(username) = candidate) LOOP
+            suffix := suffix + 1;
+            candidate := left(base, 25 - length(suffix::TEXT)) || suffix::TEXT;
+        END LOOP;
+        UPDATE users SET username = candidate, username_grace = TRUE WHERE id = account.id;
+    END LOOP;
+END
+$$;
+
+ALTER TABLE users ALTER COLUMN username SET NOT NULL;
+
+COMMIT;
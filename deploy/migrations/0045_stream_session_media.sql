@@ -0,0 +1,17 @@
+-- 0045_stream_session_media.sql
+--
+-- Ingest event tracking for live sessions. The ingest server reports when
+-- media starts and stops arriving, so a session can tell provisioned from
+-- actually live and notice an encoder dropping out. The partial index
+-- serves the job that stops sessions interrupted for too long.
+
+BEGIN;
+
+ALTER TABLE stream_sessions
+    ADD COLUMN IF NOT EXISTS receiving_media BOOLEAN NOT NULL DEFAULT FALSE,
+    ADD COLUMN IF NOT EXISTS media_confirmed_at TIMESTAMPTZ,
+    ADD COLUMN IF NOT EXISTS interrupted_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS stream_sessions_interrupted_idx ON stream_sessions (interrupted_at) WHERE interrupted_at IS NOT NULL;
+
+COMMIT;
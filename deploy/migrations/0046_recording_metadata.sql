@@ -0,0 +1,14 @@
+-- 0046_recording_metadata.sql
+--
+-- Creator-editable recording metadata. Tags are normalized the same way as
+-- channel tags, and the GIN index serves tag filters on recording lists.
+
+BEGIN;
+
+ALTER TABLE recordings
+    ADD COLUMN IF NOT EXISTS description TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS tags TEXT[] NOT NULL DEFAULT ARRAY[]::TEXT[];
+
+CREATE INDEX IF NOT EXISTS recordings_tags_idx ON recordings USING GIN (tags);
+
+COMMIT;
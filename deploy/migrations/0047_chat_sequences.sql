@@ -0,0 +1,25 @@
+-- 0047_chat_sequences.sql
+--
+-- Per-channel chat sequence numbers. chat_sequences holds the last number
+-- issued to each channel, and the chat gateway stamps every message and
+-- moderation event with the next one so reconnecting clients can ask for
+-- what they missed. Rows written before sequencing keep seq 0 and are never
+-- backfilled.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS chat_sequences (
+    channel_id TEXT PRIMARY KEY REFERENCES channels(id) ON DELETE CASCADE,
+    seq BIGINT NOT NULL DEFAULT 0
+);
+
+ALTER TABLE chat_messages
+    ADD COLUMN IF NOT EXISTS seq BIGINT NOT NULL DEFAULT 0;
+
+ALTER TABLE moderation_actions
+    ADD COLUMN IF NOT EXISTS seq BIGINT NOT NULL DEFAULT 0;
+
+CREATE INDEX IF NOT EXISTS chat_messages_channel_seq_idx ON chat_messages (channel_id, seq) WHERE seq > 0;
+CREATE INDEX IF NOT EXISTS moderation_actions_channel_seq_idx ON moderation_actions (channel_id, seq) WHERE seq > 0;
+
+COMMIT;
@@ -0,0 +1,13 @@
+-- 0048_stream_session_playback_endpoints.sql
+--
+-- Every protocol the origin serves a session over, as a JSON array of
+-- {protocol, url, priority} ordered most preferred first. playback_url keeps
+-- the preferred HLS-family URL for players that only take one. Sessions
+-- started before this migration have no endpoints recorded.
+
+BEGIN;
+
+ALTER TABLE stream_sessions
+    ADD COLUMN IF NOT EXISTS playback_endpoints JSONB;
+
+COMMIT;
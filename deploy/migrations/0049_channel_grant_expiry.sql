@@ -0,0 +1,26 @@
+-- 0049_channel_grant_expiry.sql
+--
+-- Per-channel moderator grants, and an optional expiry on moderator and
+-- editor grants. A grant with expires_at in the past no longer applies and
+-- is deleted by the periodic sweep. NULL never expires.
+
+BEGIN;
+
+ALTER TABLE channel_editors
+    ADD COLUMN IF NOT EXISTS expires_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS channel_editors_expires_idx ON channel_editors (expires_at) WHERE expires_at IS NOT NULL;
+
+CREATE TABLE IF NOT EXISTS channel_moderators (
+    channel_id TEXT NOT NULL REFERENCES channels(id) ON DELETE CASCADE,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    granted_by TEXT REFERENCES users(id) ON DELETE SET NULL,
+    granted_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    expires_at TIMESTAMPTZ,
+    PRIMARY KEY (channel_id, user_id)
+);
+
+CREATE INDEX IF NOT EXISTS channel_moderators_user_idx ON channel_moderators (user_id);
+CREATE INDEX IF NOT EXISTS channel_moderators_expires_idx ON channel_moderators (expires_at) WHERE expires_at IS NOT NULL;
+
+COMMIT;
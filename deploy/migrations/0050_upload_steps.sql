@@ -0,0 +1,12 @@
+-- 0050_upload_steps.sql
+--
+-- Tracks each upload's processing pipeline (probe, transcode, thumbnail,
+-- publish, finalize) as a JSON array of steps so creators can see which phase
+-- is running or where processing failed. The processor rewrites only this
+-- column and progress as each step starts and finishes.
+
+BEGIN;
+
+ALTER TABLE uploads ADD COLUMN IF NOT EXISTS steps JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+COMMIT;
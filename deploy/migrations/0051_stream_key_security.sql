@@ -0,0 +1,39 @@
+-- 0051_stream_key_security.sql
+--
+-- Stream key leak detection. publish_source is the address a live session's
+-- encoder first published from; publishes from any other address are refused
+-- while it is live. security_events records refused publishes, keys used
+-- from too many networks, and key rotations. notifications
+-- holds in-app notices such as those sent to owners about security events.
+
+BEGIN;
+
+ALTER TABLE stream_sessions
+    ADD COLUMN IF NOT EXISTS publish_source TEXT NOT NULL DEFAULT '';
+
+CREATE TABLE IF NOT EXISTS security_events (
+    id TEXT PRIMARY KEY,
+    channel_id TEXT NOT NULL REFERENCES channels(id) ON DELETE CASCADE,
+    kind TEXT NOT NULL,
+    source_ip TEXT NOT NULL DEFAULT '',
+    session_id TEXT NOT NULL DEFAULT '',
+    detail TEXT NOT NULL DEFAULT '',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS security_events_channel_idx ON security_events (channel_id, created_at DESC);
+
+CREATE TABLE IF NOT EXISTS notifications (
+    id TEXT PRIMARY KEY,
+    user_id TEXT NOT NULL REFERENCES users(id) ON DELETE CASCADE,
+    category TEXT NOT NULL,
+    kind TEXT NOT NULL,
+    channel_id TEXT NOT NULL DEFAULT '',
+    message TEXT NOT NULL,
+    metadata JSONB NOT NULL DEFAULT '{}'::jsonb,
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+CREATE INDEX IF NOT EXISTS notifications_user_idx ON notifications (user_id, created_at DESC);
+
+COMMIT;
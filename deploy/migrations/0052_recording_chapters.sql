@@ -0,0 +1,11 @@
+-- 0052_recording_chapters.sql
+--
+-- Stores each recording's chapters as a JSON array of {offsetSeconds, title}
+-- ordered by offset. New recordings start with their session's stream
+-- markers; creators replace the whole list when they edit it.
+
+BEGIN;
+
+ALTER TABLE recordings ADD COLUMN IF NOT EXISTS chapters JSONB NOT NULL DEFAULT '[]'::jsonb;
+
+COMMIT;
@@ -0,0 +1,17 @@
+-- 0053_channel_language_content_warnings.sql
+--
+-- Channel language and content warnings for directory filtering. language is
+-- a BCP-47 tag from the supported list, or empty when the creator has not set
+-- one; content_warnings holds values such as violence and gambling. The
+-- indexes back the directory's ?language= and ?excludeWarnings= filters.
+
+BEGIN;
+
+ALTER TABLE channels
+    ADD COLUMN IF NOT EXISTS language TEXT NOT NULL DEFAULT '',
+    ADD COLUMN IF NOT EXISTS content_warnings TEXT[] NOT NULL DEFAULT '{}';
+
+CREATE INDEX IF NOT EXISTS channels_language_idx ON channels (language) WHERE language <> '';
+CREATE INDEX IF NOT EXISTS channels_content_warnings_idx ON channels USING GIN (content_warnings);
+
+COMMIT;
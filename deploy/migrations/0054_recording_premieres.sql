@@ -0,0 +1,16 @@
+-- 0054_recording_premieres.sql
+--
+-- Scheduled premieres. premiere_at is when an unpublished recording should
+-- go public and play in sync for every viewer; it stays set afterwards so
+-- the premiere window can be derived from duration_seconds. The partial
+-- index backs the job that publishes due premieres and the directory's
+-- lookup of premieres in progress.
+
+BEGIN;
+
+ALTER TABLE recordings
+    ADD COLUMN IF NOT EXISTS premiere_at TIMESTAMPTZ;
+
+CREATE INDEX IF NOT EXISTS recordings_premiere_at_idx ON recordings (premiere_at) WHERE premiere_at IS NOT NULL;
+
+COMMIT;
@@ -0,0 +1,30 @@
+-- 0055_costream_invites.sql
+--
+-- Co-streaming. A host invites guest channels into its live session; an
+-- accepted guest's channel page mirrors the host's playback until the host
+-- stops or removes the guest. Ending the session marks its open invites
+-- ended. The partial unique index stops a guest being invited twice into the
+-- same session, and the accepted-guest index backs the directory's lookup of
+-- mirrored channels.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS costream_invites (
+    id TEXT PRIMARY KEY,
+    session_id TEXT NOT NULL REFERENCES stream_sessions(id) ON DELETE CASCADE,
+    host_channel_id TEXT NOT NULL REFERENCES channels(id) ON DELETE CASCADE,
+    guest_channel_id TEXT NOT NULL REFERENCES channels(id) ON DELETE CASCADE,
+    invited_by TEXT NOT NULL DEFAULT '',
+    status TEXT NOT NULL,
+    created_at TIMESTAMPTZ NOT NULL,
+    responded_at TIMESTAMPTZ,
+    ended_at TIMESTAMPTZ
+);
+
+CREATE UNIQUE INDEX IF NOT EXISTS costream_invites_open_guest_idx ON costream_invites (session_id, guest_channel_id) WHERE status IN ('pending', 'accepted');
+
+CREATE INDEX IF NOT EXISTS costream_invites_host_idx ON costream_invites (host_channel_id);
+
+CREATE INDEX IF NOT EXISTS costream_invites_accepted_guest_idx ON costream_invites (guest_channel_id) WHERE status = 'accepted';
+
+COMMIT;
@@ -0,0 +1,21 @@
+-- 0056_feature_flags.sql
+--
+-- Feature flags for gradual rollouts. Each flag has an on/off switch, a
+-- rollout percentage, and explicit allow and deny lists of user IDs. Which
+-- callers fall inside the percentage is derived from a hash of the flag key
+-- and the caller, so no per-user assignment is stored.
+
+BEGIN;
+
+CREATE TABLE IF NOT EXISTS feature_flags (
+    key TEXT PRIMARY KEY,
+    description TEXT NOT NULL DEFAULT '',
+    enabled BOOLEAN NOT NULL DEFAULT FALSE,
+    rollout_percent INTEGER NOT NULL DEFAULT 0 CHECK (rollout_percent BETWEEN 0 AND 100),
+    allow_user_ids TEXT[] NOT NULL DEFAULT '{}',
+    deny_user_ids TEXT[] NOT NULL DEFAULT '{}',
+    created_at TIMESTAMPTZ NOT NULL DEFAULT NOW(),
+    updated_at TIMESTAMPTZ NOT NULL DEFAULT NOW()
+);
+
+COMMIT;
@@ -0,0 +1,15 @@
+-- 0057_user_stream_limits.sql
+--
+-- Per-user override of the platform limit on how many of a user's channels
+-- may stream at once. NULL uses the platform default and zero lifts the
+-- limit. The owner index backs the count of a user's starting and live
+-- channels that StartStream checks against the limit.
+
+BEGIN;
+
+ALTER TABLE users
+    ADD COLUMN IF NOT EXISTS max_concurrent_streams INTEGER CHECK (max_concurrent_streams >= 0);
+
+CREATE INDEX IF NOT EXISTS channels_owner_streaming_idx ON channels (owner_id) WHERE current_session_id IS NOT NULL;
+
+COMMIT;
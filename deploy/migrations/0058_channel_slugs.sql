@@ -0,0 +1,54 @@
+-- 0058_channel_slugs.sql
+--
+-- Channel slugs for shareable channel URLs. Slugs are lowercase and unique.
+-- Existing channels get a slug derived from their title, else "channel",
+-- with a numeric suffix on collisions or reserved words. A renamed channel
+-- keeps its earlier slugs in channel_slug_redirects so old links still
+-- resolve; the application caps how many each channel keeps. The rules
+-- match deriveChannelSlug in internal/storage/channel_slugs.go, and the
+-- reserved words are the username ones from 0044_usernames.sql.
+
+BEGIN;
+
+ALTER TABLE channels
+    ADD COLUMN IF NOT EXISTS slug TEXT,
+    ADD COLUMN IF NOT EXISTS slug_changed_at TIMESTAMPTZ;
+
+CREATE UNIQUE INDEX IF NOT EXISTS channels_slug_idx ON channels (slug);
+
+DO $$
+DECLARE
+    channel RECORD;
+    base TEXT;
+    candidate TEXT;
+    suffix INTEGER;
+    reserved TEXT[] := ARRAY['about', 'admin', 'api', 'bitriver', 'directory', 'embed', 'help', 'login', 'logout', 'me', 'moderator', 'null', 'root', 'settings', 'signup', 'staff', 'support', 'system', 'undefined'];
+BEGIN
+    FOR channel IN SELECT id, title FROM channels WHERE slug IS NULL ORDER BY created_at, id LOOP
+        base := btrim(regexp_replace(lower(channel.title), '[^a-z0-9]+', '-', 'g'), '-');
+        base := rtrim(left(base, 40), '-');
+        IF length(base) < 3 THEN
+            base := 'channel';
+        END IF;
+        candidate := base;
+        suffix := 1;
+        WHILE candidate = ANY (reserved) OR EXISTS (SELECT 1 FROM channels WHERE slug = candidate) LOOP
+            suffix := suffix + 1;
+            candidate := rtrim(left(base, 40 - length('-' || suffix::TEXT)), '-') || '-' || suffix::TEXT;
+        END LOOP;
+        UPDATE channels SET slug = candidate WHERE id = channel.id;
+    END LOOP;
+END
+$$;
+
+ALTER TABLE channels ALTER COLUMN slug SET NOT NULL;
+
+CREATE TABLE IF NOT EXISTS channel_slug_redirects (
+    slug TEXT PRIMARY KEY,
+    channel_id TEXT NOT NULL REFERENCES channels(id) ON DELETE CASCADE,
+    created_at TIMESTAMPTZ NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS channel_slug_redirects_channel_idx ON channel_slug_redirects (channel_id, created_at DESC);
+
+COMMIT;
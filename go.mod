@@ -10,7 +10,7 @@ require (
 	github.com/redis/go-redis/v9 v9.5.1
 	golang.org/x/crypto v0.27.0
 	golang.org/x/sync v0.7.0
-	golang.org/x/text v0.18.0
+	golang.org/x/text v0.37.0
 )
 
 replace golang.org/x/crypto => ./third_party/golang.org/x/crypto
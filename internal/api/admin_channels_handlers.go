@@ -0,0 +1,180 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/authz"
+	"bitriver-live/internal/storage"
+)
+
+const (
+	// maxChannelBatchSize caps how many channels one batch request may name.
+	maxChannelBatchSize = 100
+	// channelExportFlushEvery is how many CSV rows are buffered before they
+	// are flushed to the client.
+	channelExportFlushEvery = 100
+)
+
+var channelExportHeader = []string{"id", "owner_email", "title", "category", "tags", "live_state", "followers", "created_at"}
+
+// AdminChannelsExport streams every channel as CSV for holders of
+// channels.manage_any. Rows are flushed as they are written so large
+// platforms never buffer the whole export.
+func (h *Handler) AdminChannelsExport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	user, ok := h.requirePermission(w, r, authz.ChannelsManageAny)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", `attachment; filename="channels.csv"`)
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	writer := csv.NewWriter(w)
+	flush := func() error {
+		writer.Flush()
+		if flusher != nil {
+			flusher.Flush()
+		}
+		return writer.Error()
+	}
+	if err := writer.Write(channelExportHeader); err != nil {
+		return
+	}
+	written := 0
+	err := h.Store.ExportChannels(func(row storage.ChannelExportRow) error {
+		record := []string{
+			row.Channel.ID,
+			row.OwnerEmail,
+			row.Channel.Title,
+			row.Channel.Category,
+			strings.Join(row.Channel.Tags, ";"),
+			row.Channel.LiveState,
+			strconv.Itoa(row.Followers),
+			row.Channel.CreatedAt.UTC().Format(time.RFC3339),
+		}
+		for i := range record {
+			record[i] = spreadsheetSafe(record[i])
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+		written++
+		if written%channelExportFlushEvery == 0 {
+			return flush()
+		}
+		return nil
+	})
+	if err == nil {
+		err = flush()
+	}
+	if err != nil {
+		h.logger().Error("export channels", "user_id", user.ID, "rows", written, "error", err)
+	}
+}
+
+// spreadsheetSafe prefixes values that spreadsheet applications would
+// otherwise evaluate as formulas.
+func spreadsheetSafe(value string) string {
+	if value != "" && strings.ContainsRune("=+-@\t\r", rune(value[0])) {
+		return "'" + value
+	}
+	return value
+}
+
+type channelBatchRequest struct {
+	ChannelIDs []string `json:"channelIds"`
+	Category   *string  `json:"category"`
+	AddTags    []string `json:"addTags"`
+	RemoveTags []string `json:"removeTags"`
+}
+
+type channelBatchResultResponse struct {
+	ChannelID string `json:"channelId"`
+	Status    string `json:"status"`
+	Error     string `json:"error,omitempty"`
+}
+
+type channelBatchResponse struct {
+	Results []channelBatchResultResponse `json:"results"`
+	Updated int                          `json:"updated"`
+	Skipped int                          `json:"skipped"`
+	Failed  int                          `json:"failed"`
+}
+
+// AdminChannelsBatch re-categorizes and re-tags up to maxChannelBatchSize
+// channels at once for holders of channels.manage_any. Every modified
+// channel is written to the audit log.
+func (h *Handler) AdminChannelsBatch(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+	user, ok := h.requirePermission(w, r, authz.ChannelsManageAny)
+	if !ok {
+		return
+	}
+	var req channelBatchRequest
+	if !DecodeAndValidate(w, r, &req) {
+		return
+	}
+	if len(req.ChannelIDs) == 0 {
+		WriteRequestError(w, ValidationError("channelIds is required"))
+		return
+	}
+	if len(req.ChannelIDs) > maxChannelBatchSize {
+		WriteRequestError(w, ValidationError(fmt.Sprintf("at most %d channels may be updated per batch", maxChannelBatchSize)))
+		return
+	}
+
+	results, err := h.Store.BatchUpdateChannels(req.ChannelIDs, storage.ChannelBatchUpdate{
+		Category:   req.Category,
+		AddTags:    req.AddTags,
+		RemoveTags: req.RemoveTags,
+	})
+	if err != nil {
+		writeStorageError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	response := channelBatchResponse{Results: make([]channelBatchResultResponse, 0, len(results))}
+	for _, result := range results {
+		response.Results = append(response.Results, channelBatchResultResponse{
+			ChannelID: result.ChannelID,
+			Status:    result.Status,
+			Error:     result.Error,
+		})
+		switch result.Status {
+		case storage.ChannelBatchUpdated:
+			response.Updated++
+			h.invalidateChannelCache(r.Context(), result.ChannelID)
+			fields := []any{"action", "channel.batch_update", "user_id", user.ID, "channel_id", result.ChannelID}
+			if req.Category != nil {
+				fields = append(fields, "category", strings.TrimSpace(*req.Category))
+			}
+			if len(req.AddTags) > 0 {
+				fields = append(fields, "add_tags", req.AddTags)
+			}
+			if len(req.RemoveTags) > 0 {
+				fields = append(fields, "remove_tags", req.RemoveTags)
+			}
+			h.auditLogger().Info("audit", fields...)
+		case storage.ChannelBatchSkipped:
+			response.Skipped++
+		default:
+			response.Failed++
+		}
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
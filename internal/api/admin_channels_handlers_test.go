@@ -0,0 +1,162 @@
+package api
+
+import (
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"bitriver-live/internal/authz"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+func newAdminChannelsFixture(t *testing.T) (*Handler, *storage.Storage, models.User) {
+	t.Helper()
+	handler, store := newTestHandler(t)
+	admin, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Admin", Email: "admin@example.com", Roles: []string{authz.RoleAdmin}})
+	if err != nil {
+		t.Fatalf("CreateUser admin: %v", err)
+	}
+	return handler, store, admin
+}
+
+func TestAdminChannelsExportStreamsEscapedCSV(t *testing.T) {
+	handler, store, admin := newAdminChannelsFixture(t)
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser owner: %v", err)
+	}
+	tricky, err := store.CreateChannel(owner.ID, "Live, \"loud\"\nand late", "=SUM(A1)", []string{"b", "a"})
+	if err != nil {
+		t.Fatalf("CreateChannel tricky: %v", err)
+	}
+	for i := 0; i < channelExportFlushEvery+5; i++ {
+		if _, err := store.CreateChannel(owner.ID, fmt.Sprintf("Channel %d", i), "music", nil); err != nil {
+			t.Fatalf("CreateChannel %d: %v", i, err)
+		}
+	}
+	if err := store.FollowChannel(admin.ID, tricky.ID); err != nil {
+		t.Fatalf("FollowChannel: %v", err)
+	}
+
+	req := withUser(httptest.NewRequest(http.MethodGet, "/api/admin/channels/export", nil), admin)
+	rec := httptest.NewRecorder()
+	handler.AdminChannelsExport(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Fatalf("expected CSV content type, got %q", ct)
+	}
+	if !rec.Flushed {
+		t.Fatal("expected export to flush while streaming")
+	}
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("parse CSV: %v", err)
+	}
+	if len(records) != channelExportFlushEvery+7 {
+		t.Fatalf("expected header plus %d rows, got %d", channelExportFlushEvery+6, len(records))
+	}
+	if strings.Join(records[0], ",") != strings.Join(channelExportHeader, ",") {
+		t.Fatalf("unexpected header %v", records[0])
+	}
+	row := records[1]
+	expected := []string{tricky.ID, "owner@example.com", "Live, \"loud\"\nand late", "'=SUM(A1)", "a;b", "offline", "1"}
+	for i, value := range expected {
+		if row[i] != value {
+			t.Fatalf("column %s: expected %q, got %q", channelExportHeader[i], value, row[i])
+		}
+	}
+}
+
+func TestAdminChannelsBatchReportsPartialFailures(t *testing.T) {
+	handler, store, admin := newAdminChannelsFixture(t)
+	var audit bytes.Buffer
+	handler.AuditLogger = slog.New(slog.NewJSONHandler(&audit, nil))
+
+	first, err := store.CreateChannel(admin.ID, "First", "gaming", []string{"retro"})
+	if err != nil {
+		t.Fatalf("CreateChannel first: %v", err)
+	}
+	second, err := store.CreateChannel(admin.ID, "Second", "music", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel second: %v", err)
+	}
+	third, err := store.CreateChannel(admin.ID, "Third", "art", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel third: %v", err)
+	}
+
+	body := fmt.Sprintf(`{"channelIds":[%q,%q,"missing",%q],"category":"music","addTags":["Chill"]}`, first.ID, second.ID, third.ID)
+	req := withUser(httptest.NewRequest(http.MethodPost, "/api/admin/channels/batch", strings.NewReader(body)), admin)
+	rec := httptest.NewRecorder()
+	handler.AdminChannelsBatch(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var payload channelBatchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if payload.Updated != 3 || payload.Failed != 1 || payload.Skipped != 0 || len(payload.Results) != 4 {
+		t.Fatalf("unexpected batch summary %+v", payload)
+	}
+	if missing := payload.Results[2]; missing.ChannelID != "missing" || missing.Status != storage.ChannelBatchFailed || missing.Error == "" {
+		t.Fatalf("expected missing channel to fail, got %+v", missing)
+	}
+	if updated, _ := store.GetChannel(third.ID); updated.Category != "music" || strings.Join(updated.Tags, ",") != "chill" {
+		t.Fatalf("expected third channel updated, got %+v", updated)
+	}
+	if count := strings.Count(audit.String(), `"action":"channel.batch_update"`); count != 3 {
+		t.Fatalf("expected 3 audit events, got %d:\n%s", count, audit.String())
+	}
+
+	audit.Reset()
+	rec = httptest.NewRecorder()
+	handler.AdminChannelsBatch(rec, withUser(httptest.NewRequest(http.MethodPost, "/api/admin/channels/batch", strings.NewReader(body)), admin))
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode repeat response: %v", err)
+	}
+	if payload.Updated != 0 || payload.Skipped != 3 || audit.Len() != 0 {
+		t.Fatalf("expected repeat batch to skip without auditing, got %+v audit=%q", payload, audit.String())
+	}
+}
+
+func TestAdminChannelsBatchRejectsInvalidRequests(t *testing.T) {
+	handler, store, admin := newAdminChannelsFixture(t)
+	channel, err := store.CreateChannel(admin.ID, "Studio", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	tooMany := make([]string, maxChannelBatchSize+1)
+	for i := range tooMany {
+		tooMany[i] = channel.ID
+	}
+	oversized, _ := json.Marshal(map[string]any{"channelIds": tooMany, "category": "music"})
+
+	cases := map[string]string{
+		"too many channels": string(oversized),
+		"no channels":       `{"category":"music"}`,
+		"no changes":        fmt.Sprintf(`{"channelIds":[%q]}`, channel.ID),
+		"invalid category":  fmt.Sprintf(`{"channelIds":[%q],"category":%q}`, channel.ID, strings.Repeat("x", storage.MaxChannelCategoryLength+1)),
+	}
+	for name, body := range cases {
+		rec := httptest.NewRecorder()
+		handler.AdminChannelsBatch(rec, withUser(httptest.NewRequest(http.MethodPost, "/api/admin/channels/batch", strings.NewReader(body)), admin))
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d: %s", name, rec.Code, rec.Body.String())
+		}
+	}
+	if unchanged, _ := store.GetChannel(channel.ID); unchanged.Category != "gaming" {
+		t.Fatalf("expected rejected batches to leave the channel untouched, got %q", unchanged.Category)
+	}
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"bitriver-live/internal/authz"
 	"bitriver-live/internal/models"
 	"bitriver-live/internal/observability/metrics"
 )
@@ -36,19 +37,22 @@ func (h *Handler) AnalyticsOverview(w http.ResponseWriter, r *http.Request) {
 		WriteMethodNotAllowed(w, r, http.MethodGet)
 		return
 	}
-	if _, ok := h.requireRole(w, r, roleAdmin); !ok {
+	if _, ok := h.requirePermission(w, r, authz.AnalyticsView); !ok {
 		return
 	}
 	payload, err := h.computeAnalyticsOverview(time.Now().UTC())
 	if err != nil {
-		WriteError(w, http.StatusInternalServerError, err)
+		writeStorageError(w, http.StatusInternalServerError, err)
 		return
 	}
 	WriteJSON(w, http.StatusOK, payload)
 }
 
 func (h *Handler) computeAnalyticsOverview(now time.Time) (analyticsOverviewResponse, error) {
-	channels := h.Store.ListChannels("", "")
+	channels, err := h.Store.ListChannels("", "")
+	if err != nil {
+		return analyticsOverviewResponse{}, err
+	}
 	startOfDay := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
 	windowStart := now.Add(-24 * time.Hour)
 	summary := analyticsSummaryResponse{}
@@ -4,9 +4,13 @@ import (
 	"context"
 	"fmt"
 	"net/http"
+	"strings"
 	"time"
 
+	"bitriver-live/internal/auth"
+	"bitriver-live/internal/authz"
 	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
 )
 
 // contextKey is a private type used to avoid collisions when storing values
@@ -16,11 +20,15 @@ type contextKey string
 const (
 	// userContextKey is the context key under which the authenticated user is stored.
 	userContextKey contextKey = "authenticatedUser"
-
-	// roleAdmin represents a site-wide administrator with full access.
-	roleAdmin = "admin"
-	// roleCreator represents a content creator who owns one or more channels.
-	roleCreator = "creator"
+	// apiTokenContextKey is the context key under which the personal access
+	// token used to authenticate the request is stored.
+	apiTokenContextKey contextKey = "authenticatedAPIToken"
+	// impersonatorContextKey is the context key under which the administrator
+	// acting as the authenticated user is stored.
+	impersonatorContextKey contextKey = "impersonatingAdmin"
+	// clientIPContextKey is the context key under which the client address
+	// resolved by the server is stored.
+	clientIPContextKey contextKey = "clientIP"
 )
 
 // ContextWithUser stores the authenticated user in the provided context.
@@ -40,32 +48,116 @@ func UserFromContext(ctx context.Context) (models.User, bool) {
 	return user, ok
 }
 
+// ContextWithImpersonator records the administrator behind an impersonation
+// session. The authenticated user stored by ContextWithUser remains the
+// impersonated account, so handlers behave exactly as they would for them.
+func ContextWithImpersonator(ctx context.Context, admin models.User) context.Context {
+	return context.WithValue(ctx, impersonatorContextKey, admin)
+}
+
+// ImpersonatorFromContext returns the administrator impersonating the
+// authenticated user, if the request was made under impersonation.
+func ImpersonatorFromContext(ctx context.Context) (models.User, bool) {
+	admin, ok := ctx.Value(impersonatorContextKey).(models.User)
+	return admin, ok
+}
+
+// ContextWithAPIToken records the personal access token that authenticated the
+// request so handlers can enforce its scopes.
+func ContextWithAPIToken(ctx context.Context, token models.APIToken) context.Context {
+	return context.WithValue(ctx, apiTokenContextKey, token)
+}
+
+// APITokenFromContext retrieves the personal access token used for the
+// request, if any. Session-authenticated requests carry no token.
+func APITokenFromContext(ctx context.Context) (models.APIToken, bool) {
+	token, ok := ctx.Value(apiTokenContextKey).(models.APIToken)
+	return token, ok
+}
+
+// ContextWithClientIP records the client address the server resolved for the
+// request, which honours trusted proxy headers where r.RemoteAddr cannot.
+func ContextWithClientIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, clientIPContextKey, ip)
+}
+
+// ClientIPFromContext returns the address stored by ContextWithClientIP.
+func ClientIPFromContext(ctx context.Context) (string, bool) {
+	ip, ok := ctx.Value(clientIPContextKey).(string)
+	return ip, ok && ip != ""
+}
+
+// IsAPIToken reports whether the bearer value is a personal access token
+// rather than a session token.
+func IsAPIToken(token string) bool {
+	return strings.HasPrefix(token, storage.APITokenPrefix)
+}
+
+// AuthenticateAPIToken resolves the personal access token presented in the
+// Authorization header to its owner.
+func (h *Handler) AuthenticateAPIToken(r *http.Request) (models.User, models.APIToken, error) {
+	secret := ExtractToken(r)
+	if !IsAPIToken(secret) {
+		return models.User{}, models.APIToken{}, fmt.Errorf("missing access token")
+	}
+	token, user, err := h.Store.AuthenticateAPIToken(secret)
+	if err != nil {
+		return models.User{}, models.APIToken{}, err
+	}
+	return user, token, nil
+}
+
 // AuthenticateRequest validates the session token on the request and returns
 // the associated user alongside the refreshed session expiry when available.
 //
 // The token is extracted using ExtractToken (e.g., from cookies or headers)
 // and validated via the sessionManager. If the token is missing, invalid,
-// expired, or the user no longer exists, an error is returned.
+// expired, or the user no longer exists or is deactivated, an error is
+// returned.
 func (h *Handler) AuthenticateRequest(r *http.Request) (models.User, time.Time, error) {
+	user, session, err := h.AuthenticateSession(r)
+	if err != nil {
+		return models.User{}, time.Time{}, err
+	}
+	return user, session.ExpiresAt, nil
+}
+
+// AuthenticateSession behaves like AuthenticateRequest but returns the whole
+// session record. For impersonation sessions it also checks that the
+// administrator still exists, is active, and may impersonate, revoking the
+// session otherwise.
+func (h *Handler) AuthenticateSession(r *http.Request) (models.User, auth.SessionRecord, error) {
 	token := ExtractToken(r)
 	if token == "" {
-		return models.User{}, time.Time{}, fmt.Errorf("missing session token")
+		return models.User{}, auth.SessionRecord{}, fmt.Errorf("missing session token")
 	}
 
-	userID, expiresAt, ok, err := h.sessionManager().Validate(token)
+	session, ok, err := h.sessionManager().ValidateSession(token)
 	if err != nil {
-		return models.User{}, time.Time{}, fmt.Errorf("session validation failed: %w", err)
+		return models.User{}, auth.SessionRecord{}, fmt.Errorf("session validation failed: %w", err)
 	}
 	if !ok {
-		return models.User{}, time.Time{}, fmt.Errorf("invalid or expired session")
+		return models.User{}, auth.SessionRecord{}, fmt.Errorf("invalid or expired session")
 	}
 
-	user, exists := h.Store.GetUser(userID)
+	user, exists := h.Store.GetUser(session.UserID)
 	if !exists {
-		return models.User{}, time.Time{}, fmt.Errorf("account not found")
+		return models.User{}, auth.SessionRecord{}, fmt.Errorf("account not found")
+	}
+	if user.Deactivated() {
+		// Revoke the session so a store that cannot revoke by user stops
+		// presenting it.
+		_ = h.sessionManager().Revoke(token)
+		return models.User{}, auth.SessionRecord{}, fmt.Errorf("account is deactivated")
+	}
+	if session.Impersonated() {
+		if _, err := h.Impersonator(session); err != nil {
+			_ = h.sessionManager().Revoke(token)
+			return models.User{}, auth.SessionRecord{}, err
+		}
 	}
 
-	return user, expiresAt, nil
+	return user, session, nil
 }
 
 // requireAuthenticatedUser ensures that a request has an authenticated user
@@ -82,58 +174,103 @@ func (h *Handler) requireAuthenticatedUser(w http.ResponseWriter, r *http.Reques
 	return user, true
 }
 
-// requireRole ensures that the current user has at least one of the provided
-// roles before allowing the request to proceed.
+// requirePermission ensures that the current user holds the provided
+// platform-wide permission before allowing the request to proceed.
 //
-// If the user is not authenticated, a 401 is returned. If the user does not
-// have any of the required roles, a 403 Forbidden response is written.
-// When roles is empty, any authenticated user is allowed.
-func (h *Handler) requireRole(w http.ResponseWriter, r *http.Request, roles ...string) (models.User, bool) {
+// If the user is not authenticated, a 401 is returned. If none of the user's
+// roles grant the permission, a 403 Forbidden response is written.
+func (h *Handler) requirePermission(w http.ResponseWriter, r *http.Request, permission authz.Permission) (models.User, bool) {
 	user, ok := h.requireAuthenticatedUser(w, r)
 	if !ok {
 		return models.User{}, false
 	}
-	if len(roles) == 0 {
-		return user, true
-	}
-	if !userHasAnyRole(user, roles...) {
+	if !authz.Has(user, permission) {
 		WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
 		return models.User{}, false
 	}
 	return user, true
 }
 
-// userHasAnyRole reports whether the user has at least one of the provided
-// roles. If roles is empty, it returns true.
-func userHasAnyRole(user models.User, roles ...string) bool {
-	if len(roles) == 0 {
+// canManageChannel reports whether the user may change the channel's settings
+// and stream lifecycle: creators manage the channels they own, and holders of
+// channels.manage_any manage every channel.
+func canManageChannel(user models.User, channel models.Channel) bool {
+	if authz.Has(user, authz.ChannelsManageAny) {
 		return true
 	}
-	for _, required := range roles {
-		if user.HasRole(required) {
-			return true
-		}
+	return channel.OwnerID == user.ID && authz.Has(user, authz.ChannelsCreate)
+}
+
+// canViewChannelDetails reports whether the user may see owner-only channel
+// fields such as the stream key hint.
+func canViewChannelDetails(user models.User, channel models.Channel) bool {
+	return channel.OwnerID == user.ID || authz.Has(user, authz.ChannelsManageAny)
+}
+
+// canManageChannelMedia reports whether the user may manage the channel's
+// recordings and uploads. Besides the owner and holders of
+// recordings.manage_any, editors with a grant on the channel qualify.
+func (h *Handler) canManageChannelMedia(user models.User, channel models.Channel) bool {
+	if user.ID == "" {
+		return false
+	}
+	if channel.OwnerID == user.ID || authz.Has(user, authz.RecordingsManageAny) {
+		return true
+	}
+	return authz.Has(user, authz.RecordingsManageGranted) && h.Store.IsChannelEditor(channel.ID, user.ID)
+}
+
+// canModerateChannel reports whether the user may moderate the channel's
+// chat: the owner, holders of chat.moderate_any, and users with an
+// unexpired channel moderator grant.
+func (h *Handler) canModerateChannel(user models.User, channel models.Channel) bool {
+	if authz.CanModerateChannel(user, channel) {
+		return true
+	}
+	return user.ID != "" && h.Store.IsChannelModerator(channel.ID, user.ID)
+}
+
+// canViewChannelMonetization reports whether the user may list the channel's
+// tips and subscriptions.
+func canViewChannelMonetization(user models.User, channel models.Channel) bool {
+	return channel.OwnerID == user.ID || authz.Has(user, authz.MonetizationViewAny)
+}
+
+// requireScope ensures that requests authenticated with a personal access
+// token carry the provided scope. Session-authenticated requests are not
+// scope-limited.
+//
+// On failure, a 403 Forbidden response is written and false is returned.
+func (h *Handler) requireScope(w http.ResponseWriter, r *http.Request, scope string) bool {
+	token, ok := APITokenFromContext(r.Context())
+	if !ok || token.HasScope(scope) {
+		return true
 	}
+	WriteError(w, http.StatusForbidden, fmt.Errorf("access token missing %s scope", scope))
 	return false
 }
 
-// ensureChannelAccess verifies that the current user has permission to access
+// ensureChannelAccess verifies that the current user has permission to manage
 // the given channel.
 //
 // Access rules:
-//   - The user must be authenticated and have either the admin or creator role.
-//   - Admins may access any channel.
-//   - Creators may only access channels where channel.OwnerID matches their ID.
+//   - The user must be authenticated and satisfy canManageChannel.
+//   - Personal access tokens need the manage-channel scope for writes.
 //
 // On failure, a 401 or 403 response is written and false is returned.
 func (h *Handler) ensureChannelAccess(w http.ResponseWriter, r *http.Request, channel models.Channel) (models.User, bool) {
-	user, ok := h.requireRole(w, r, roleAdmin, roleCreator)
+	user, ok := h.requireAuthenticatedUser(w, r)
 	if !ok {
 		return models.User{}, false
 	}
-	if channel.OwnerID != user.ID && !user.HasRole(roleAdmin) {
+	if !canManageChannel(user, channel) {
 		WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
 		return models.User{}, false
 	}
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		if !h.requireScope(w, r, storage.APITokenScopeManageChannel) {
+			return models.User{}, false
+		}
+	}
 	return user, true
 }
@@ -0,0 +1,66 @@
+package api
+
+import (
+	"net"
+	"net/http"
+	"strings"
+
+	"bitriver-live/internal/auth"
+	"bitriver-live/internal/observability/metrics"
+)
+
+// auditAuth records an authentication event for r on the audit logger,
+// adding the client address and user agent. Failed sign-ins are also counted
+// by the class of address they came from so bursts can be alerted on.
+func (h *Handler) auditAuth(r *http.Request, event auth.AuditEvent) {
+	event.RemoteIP = requestClientIP(r)
+	event.UserAgent = r.UserAgent()
+	if admin, ok := ImpersonatorFromContext(r.Context()); ok && event.ImpersonatorID == "" {
+		event.ImpersonatorID = admin.ID
+	}
+	if event.Action == auth.AuditActionLoginFailure {
+		metrics.Default().ObserveLoginFailure(ipClass(event.RemoteIP), event.Reason)
+	}
+	auth.EmitAudit(r.Context(), h.auditLogger(), event)
+}
+
+// auditLoginFailure records a failed sign-in with the email or provider it
+// named. Only the reason category is kept, never the credentials tried.
+func (h *Handler) auditLoginFailure(r *http.Request, email, provider, reason string) {
+	h.auditAuth(r, auth.AuditEvent{
+		Action:   auth.AuditActionLoginFailure,
+		Email:    strings.ToLower(strings.TrimSpace(email)),
+		Provider: provider,
+		Reason:   reason,
+	})
+}
+
+// requestClientIP returns the address the server resolved for r, falling
+// back to the host of r.RemoteAddr when none was recorded.
+func requestClientIP(r *http.Request) string {
+	if ip, ok := ClientIPFromContext(r.Context()); ok {
+		return ip
+	}
+	host, _, err := net.SplitHostPort(r.RemoteAddr)
+	if err != nil {
+		return r.RemoteAddr
+	}
+	return host
+}
+
+// ipClass buckets a client address for metrics: "loopback", "private" for
+// private and link-local ranges, "public", or "unknown" when it does not
+// parse.
+func ipClass(raw string) string {
+	ip := net.ParseIP(raw)
+	switch {
+	case ip == nil:
+		return "unknown"
+	case ip.IsLoopback():
+		return "loopback"
+	case ip.IsPrivate(), ip.IsLinkLocalUnicast():
+		return "private"
+	default:
+		return "public"
+	}
+}
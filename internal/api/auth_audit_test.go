@@ -0,0 +1,143 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"bitriver-live/internal/auth"
+	"bitriver-live/internal/observability/logging"
+	"bitriver-live/internal/observability/metrics"
+)
+
+func TestAuthLifecycleEmitsAuditEvents(t *testing.T) {
+	handler, _ := newTestHandler(t)
+	var audit bytes.Buffer
+	handler.AuditLogger = slog.New(slog.NewJSONHandler(&audit, nil))
+
+	failureLabel := metrics.LoginFailureLabel{IPClass: "public", Reason: auth.AuditReasonInvalidCredentials}
+	failuresBefore := metrics.Default().LoginFailureCounts()[failureLabel]
+
+	serve := func(method, path, body, token string, fn http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.RemoteAddr = "203.0.113.5:4321"
+		req.Header.Set("User-Agent", "audit-test/1.0")
+		if token != "" {
+			req.AddCookie(&http.Cookie{Name: "bitriver_session", Value: token})
+		}
+		req = req.WithContext(logging.ContextWithRequestID(req.Context(), "req-audit"))
+		rec := httptest.NewRecorder()
+		fn(rec, req)
+		return rec
+	}
+	sessionToken := func(rec *httptest.ResponseRecorder) string {
+		return findCookie(t, rec.Result().Cookies(), "bitriver_session").Value
+	}
+
+	rec := serve(http.MethodPost, "/api/auth/signup", `{"displayName":"Ada","email":"ada@example.com","password":"first-secret"}`, "", handler.Signup)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected signup to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = serve(http.MethodPost, "/api/auth/login", `{"email":" Ada@Example.com ","password":"guessed-secret"}`, "", handler.Login)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a wrong password to be rejected, got %d", rec.Code)
+	}
+	rec = serve(http.MethodPost, "/api/auth/login", `{"email":"ada@example.com","password":"first-secret"}`, "", handler.Login)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected login to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	token := sessionToken(rec)
+	user, _, ok, err := handler.sessionManager().Validate(token)
+	if err != nil || !ok {
+		t.Fatalf("expected the login session to validate: %v", err)
+	}
+
+	changePassword := func(body string) *httptest.ResponseRecorder {
+		return serve(http.MethodPost, "/api/users/me/password", body, token, func(w http.ResponseWriter, r *http.Request) {
+			account, ok := handler.Store.GetUser(user)
+			if !ok {
+				t.Fatalf("expected user %s to exist", user)
+			}
+			handler.UserByID(w, withUser(r, account))
+		})
+	}
+	if rec := changePassword(`{"currentPassword":"guessed-secret","newPassword":"second-secret"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected a wrong current password to be rejected, got %d", rec.Code)
+	}
+	if rec := changePassword(`{"currentPassword":"first-secret","newPassword":"second-secret"}`); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected the password change to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := serve(http.MethodDelete, "/api/auth/session", "", token, handler.Session); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected logout to succeed, got %d", rec.Code)
+	}
+
+	for _, secret := range []string{"first-secret", "guessed-secret", "second-secret", token} {
+		if strings.Contains(audit.String(), secret) {
+			t.Fatalf("expected the audit log to omit credentials, found %q in %s", secret, audit.String())
+		}
+	}
+
+	var events []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(audit.String()), "\n") {
+		var event map[string]any
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("decode audit line %q: %v", line, err)
+		}
+		events = append(events, event)
+	}
+	want := []struct {
+		action string
+		fields map[string]string
+	}{
+		{auth.AuditActionSignup, map[string]string{"user_id": user, "actor_id": user}},
+		{auth.AuditActionLoginFailure, map[string]string{"email": "ada@example.com", "reason": auth.AuditReasonInvalidCredentials}},
+		{auth.AuditActionLogin, map[string]string{"user_id": user}},
+		{auth.AuditActionPasswordFailure, map[string]string{"user_id": user, "reason": auth.AuditReasonInvalidCredentials}},
+		{auth.AuditActionPasswordChange, map[string]string{"user_id": user, "actor_id": user}},
+		{auth.AuditActionLogout, map[string]string{"user_id": user}},
+	}
+	if len(events) != len(want) {
+		t.Fatalf("expected %d audit events, got %d: %s", len(want), len(events), audit.String())
+	}
+	for i, expected := range want {
+		event := events[i]
+		if event["action"] != expected.action {
+			t.Fatalf("event %d: expected action %s, got %v", i, expected.action, event["action"])
+		}
+		fields := map[string]string{"remote_ip": "203.0.113.5", "user_agent": "audit-test/1.0", "request_id": "req-audit"}
+		for key, value := range expected.fields {
+			fields[key] = value
+		}
+		for key, value := range fields {
+			if event[key] != value {
+				t.Fatalf("event %d (%s): expected %s=%q, got %v", i, expected.action, key, value, event[key])
+			}
+		}
+	}
+
+	if got := metrics.Default().LoginFailureCounts()[failureLabel]; got != failuresBefore+1 {
+		t.Fatalf("expected one public invalid_credentials failure to be counted, got %d more", got-failuresBefore)
+	}
+}
+
+func TestIPClass(t *testing.T) {
+	cases := map[string]string{
+		"127.0.0.1":   "loopback",
+		"::1":         "loopback",
+		"10.1.2.3":    "private",
+		"192.168.0.9": "private",
+		"fe80::1":     "private",
+		"203.0.113.5": "public",
+		"not-an-ip":   "unknown",
+		"":            "unknown",
+	}
+	for raw, want := range cases {
+		if got := ipClass(raw); got != want {
+			t.Errorf("ipClass(%q) = %q, want %q", raw, got, want)
+		}
+	}
+}
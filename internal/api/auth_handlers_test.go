@@ -6,6 +6,7 @@ import (
 	"encoding/json"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"testing"
 	"time"
 
@@ -220,3 +221,89 @@ func TestOAuthCallbackSessionCookiePolicy(t *testing.T) {
 		t.Fatalf("expected positive MaxAge, got %d", cookie.MaxAge)
 	}
 }
+
+func TestOAuthFlowRejectsOpenRedirectsAndForeignCallbacks(t *testing.T) {
+	provider := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			_ = json.NewEncoder(w).Encode(map[string]string{"access_token": "token-1"})
+		case "/userinfo":
+			_ = json.NewEncoder(w).Encode(map[string]string{"id": "sub-1", "email": "viewer@example.com", "name": "Viewer"})
+		}
+	}))
+	defer provider.Close()
+	manager, err := oauth.NewManager([]oauth.ProviderConfig{{
+		Name:         "test",
+		DisplayName:  "Test",
+		AuthorizeURL: provider.URL + "/authorize",
+		TokenURL:     provider.URL + "/token",
+		UserInfoURL:  provider.URL + "/userinfo",
+		ClientID:     "client",
+		ClientSecret: "secret",
+		RedirectURL:  "https://live.example.com/api/auth/oauth/test/callback",
+		Profile:      oauth.ProfileMapping{IDField: "id", EmailField: "email", NameField: "name"},
+	}}, oauth.WithReturnOrigins([]string{"https://app.example.com"}))
+	if err != nil {
+		t.Fatalf("NewManager: %v", err)
+	}
+	handler, _ := newTestHandler(t)
+	handler.OAuth = manager
+
+	start := func(returnTo string) (*httptest.ResponseRecorder, string) {
+		body, _ := json.Marshal(oauthStartRequest{ReturnTo: returnTo})
+		rec := httptest.NewRecorder()
+		handler.OAuthByProvider(rec, httptest.NewRequest(http.MethodPost, "/api/auth/oauth/test/start", bytes.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			return rec, ""
+		}
+		var payload map[string]string
+		if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+			t.Fatalf("decode start response: %v", err)
+		}
+		authorize, err := url.Parse(payload["url"])
+		if err != nil {
+			t.Fatalf("parse authorize url: %v", err)
+		}
+		return rec, authorize.Query().Get("state")
+	}
+
+	for _, target := range []string{"https://evil.example.com/", "//evil.example.com", "/\\evil.example.com"} {
+		if rec, _ := start(target); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%q: expected 400, got %d: %s", target, rec.Code, rec.Body.String())
+		}
+	}
+
+	rec, state := start("https://app.example.com/welcome")
+	flow := findCookie(t, rec.Result().Cookies(), oauthFlowCookieName)
+	if flow.Value == "" || flow.SameSite != http.SameSiteLaxMode || !flow.HttpOnly {
+		t.Fatalf("expected an HttpOnly Lax flow cookie, got %+v", flow)
+	}
+
+	// A callback from a browser without the flow cookie burns the state.
+	callback := "/api/auth/oauth/test/callback?code=xyz&state=" + state
+	rec = httptest.NewRecorder()
+	handler.OAuthByProvider(rec, httptest.NewRequest(http.MethodGet, callback, nil))
+	if rec.Code != http.StatusSeeOther || rec.Header().Get("Location") != "/?oauth=error" {
+		t.Fatalf("expected a redirect home with an error, got %d %q", rec.Code, rec.Header().Get("Location"))
+	}
+	req := httptest.NewRequest(http.MethodGet, callback, nil)
+	req.AddCookie(flow)
+	rec = httptest.NewRecorder()
+	handler.OAuthByProvider(rec, req)
+	if location := rec.Header().Get("Location"); location != "/?oauth=error" {
+		t.Fatalf("expected the used state to be rejected, got %q", location)
+	}
+
+	rec, state = start("https://app.example.com/welcome")
+	flow = findCookie(t, rec.Result().Cookies(), oauthFlowCookieName)
+	req = httptest.NewRequest(http.MethodGet, "/api/auth/oauth/test/callback?code=xyz&state="+state, nil)
+	req.AddCookie(flow)
+	rec = httptest.NewRecorder()
+	handler.OAuthByProvider(rec, req)
+	if location := rec.Header().Get("Location"); location != "https://app.example.com/welcome?oauth=success" {
+		t.Fatalf("expected a redirect to the allowed origin, got %q", location)
+	}
+	if cookie := findCookie(t, rec.Result().Cookies(), oauthFlowCookieName); cookie.MaxAge >= 0 {
+		t.Fatalf("expected the flow cookie to be cleared, got %+v", cookie)
+	}
+}
@@ -9,7 +9,9 @@ import (
 	"strings"
 	"time"
 
+	"bitriver-live/internal/auth"
 	"bitriver-live/internal/auth/oauth"
+	"bitriver-live/internal/authz"
 	"bitriver-live/internal/models"
 	"bitriver-live/internal/storage"
 )
@@ -36,10 +38,14 @@ func (h *Handler) Signup(w http.ResponseWriter, r *http.Request) {
 
 	user, err := h.Store.CreateUser(storage.CreateUserParams{
 		DisplayName: req.DisplayName,
+		Username:    req.Username,
 		Email:       req.Email,
 		Password:    req.Password,
 		SelfSignup:  true,
 	})
+	if writeUsernameError(w, err) {
+		return
+	}
 	if err != nil {
 		slog.Error("signup create user failed", "email", req.Email, "error", err)
 		WriteRequestError(w, RequestError{Status: http.StatusBadRequest, CodeVal: "signup_failed", Message: "unable to create account", Err: err})
@@ -53,6 +59,7 @@ func (h *Handler) Signup(w http.ResponseWriter, r *http.Request) {
 	}
 
 	h.setSessionCookie(w, r, token, expiresAt)
+	h.auditAuth(r, auth.AuditEvent{Action: auth.AuditActionSignup, ActorID: user.ID, UserID: user.ID})
 	WriteJSON(w, http.StatusCreated, newAuthResponse(user, expiresAt))
 }
 
@@ -68,7 +75,13 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	user, err := h.Store.AuthenticateUser(req.Email, req.Password)
+	if errors.Is(err, storage.ErrUserDeactivated) {
+		h.auditLoginFailure(r, req.Email, "", auth.AuditReasonAccountDeactivated)
+		WriteRequestError(w, RequestError{Status: http.StatusForbidden, CodeVal: "account_deactivated", Message: "this account has been deactivated", Err: err})
+		return
+	}
 	if err != nil {
+		h.auditLoginFailure(r, req.Email, "", auth.AuditReasonInvalidCredentials)
 		WriteRequestError(w, RequestError{Status: http.StatusUnauthorized, CodeVal: "invalid_credentials", Message: "invalid credentials", Err: err})
 		return
 	}
@@ -80,6 +93,7 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 	}
 
 	h.setSessionCookie(w, r, token, expiresAt)
+	h.auditAuth(r, auth.AuditEvent{Action: auth.AuditActionLogin, ActorID: user.ID, UserID: user.ID})
 	WriteJSON(w, http.StatusOK, newAuthResponse(user, expiresAt))
 }
 
@@ -131,15 +145,25 @@ func (h *Handler) oauthStart(w http.ResponseWriter, r *http.Request, provider st
 	if !DecodeAndValidate(w, r, &req) {
 		return
 	}
-	begin, err := h.OAuth.Begin(provider, sanitizeReturnPath(req.ReturnTo))
+	nonce, err := oauth.GenerateState()
+	if err != nil {
+		writeStorageError(w, http.StatusInternalServerError, err)
+		return
+	}
+	begin, err := h.OAuth.Begin(provider, req.ReturnTo, oauth.Binding{Session: nonce, IP: requestClientIP(r)})
 	if errors.Is(err, oauth.ErrProviderNotConfigured) {
 		WriteError(w, http.StatusNotFound, fmt.Errorf("oauth provider %s not configured", provider))
 		return
 	}
+	if errors.Is(err, oauth.ErrReturnToInvalid) {
+		WriteRequestError(w, ValidationError("returnTo must be a path on this site or an allowed origin"))
+		return
+	}
 	if err != nil {
-		WriteError(w, http.StatusInternalServerError, err)
+		writeStorageError(w, http.StatusInternalServerError, err)
 		return
 	}
+	setOAuthFlowCookie(w, r, nonce, h.now().Add(oauthFlowCookieTTL), h.sessionCookiePolicy())
 	WriteJSON(w, http.StatusOK, map[string]string{"url": begin.URL})
 }
 
@@ -151,12 +175,15 @@ func (h *Handler) oauthCallback(w http.ResponseWriter, r *http.Request, provider
 
 	query := r.URL.Query()
 	state := query.Get("state")
+	binding := oauthFlowBinding(r)
 	if errParam := query.Get("error"); errParam != "" {
+		h.auditLoginFailure(r, "", provider, auth.AuditReasonProviderError)
+		clearOAuthFlowCookie(w, r, h.sessionCookiePolicy())
 		redirectTarget := "/"
-		if dest, err := h.OAuth.Cancel(state); err == nil {
+		if dest, err := h.OAuth.Cancel(state, binding); err == nil && dest != "" {
 			redirectTarget = dest
 		}
-		http.Redirect(w, r, appendQueryParam(sanitizeReturnPath(redirectTarget), "oauth", "error"), http.StatusSeeOther)
+		http.Redirect(w, r, appendQueryParam(redirectTarget, "oauth", "error"), http.StatusSeeOther)
 		return
 	}
 
@@ -170,8 +197,11 @@ func (h *Handler) oauthCallback(w http.ResponseWriter, r *http.Request, provider
 		return
 	}
 
-	completion, err := h.OAuth.Complete(provider, state, code)
-	returnPath := sanitizeReturnPath(completion.ReturnTo)
+	// The manager validates the return URL again before handing it back, so
+	// it is safe to redirect to as is.
+	completion, err := h.OAuth.Complete(provider, state, code, binding)
+	clearOAuthFlowCookie(w, r, h.sessionCookiePolicy())
+	returnPath := completion.ReturnTo
 	if returnPath == "" {
 		returnPath = "/"
 	}
@@ -180,6 +210,7 @@ func (h *Handler) oauthCallback(w http.ResponseWriter, r *http.Request, provider
 			WriteRequestError(w, RequestError{Status: http.StatusNotFound, Message: fmt.Sprintf("oauth provider %s not configured", provider)})
 			return
 		}
+		h.auditLoginFailure(r, "", provider, auth.AuditReasonProviderError)
 		http.Redirect(w, r, appendQueryParam(returnPath, "oauth", "error"), http.StatusSeeOther)
 		return
 	}
@@ -191,6 +222,9 @@ func (h *Handler) oauthCallback(w http.ResponseWriter, r *http.Request, provider
 		DisplayName: completion.Profile.DisplayName,
 	})
 	if err != nil {
+		if errors.Is(err, storage.ErrUserDeactivated) {
+			h.auditLoginFailure(r, completion.Profile.Email, provider, auth.AuditReasonAccountDeactivated)
+		}
 		http.Redirect(w, r, appendQueryParam(returnPath, "oauth", "error"), http.StatusSeeOther)
 		return
 	}
@@ -201,44 +235,19 @@ func (h *Handler) oauthCallback(w http.ResponseWriter, r *http.Request, provider
 		return
 	}
 	h.setSessionCookie(w, r, token, expiresAt)
+	// AuthenticateOAuth links the provider account to the user on first use,
+	// so this one event covers both the link and later sign-ins.
+	h.auditAuth(r, auth.AuditEvent{Action: auth.AuditActionOAuthLogin, ActorID: user.ID, UserID: user.ID, Provider: provider})
 	http.Redirect(w, r, appendQueryParam(returnPath, "oauth", "success"), http.StatusSeeOther)
 }
 
-func sanitizeReturnPath(input string) string {
-	trimmed := strings.TrimSpace(input)
-	if trimmed == "" {
-		return "/"
-	}
-	parsed, err := url.Parse(trimmed)
-	if err == nil {
-		if parsed.IsAbs() {
-			trimmed = parsed.Path
-			if parsed.RawQuery != "" {
-				trimmed = trimmed + "?" + parsed.RawQuery
-			}
-		} else {
-			trimmed = parsed.RequestURI()
-		}
-	}
-	trimmed = strings.TrimSpace(trimmed)
-	if trimmed == "" || strings.HasPrefix(trimmed, "//") {
-		return "/"
-	}
-	if !strings.HasPrefix(trimmed, "/") {
-		trimmed = "/" + trimmed
-	}
-	return trimmed
-}
-
+// appendQueryParam adds key=value to a return URL the OAuth manager has
+// already validated, which may be absolute on an allowed origin.
 func appendQueryParam(path, key, value string) string {
 	parsed, err := url.Parse(path)
 	if err != nil {
 		parsed = &url.URL{Path: path}
 	}
-	if parsed.Scheme != "" && parsed.Host != "" {
-		parsed.Scheme = ""
-		parsed.Host = ""
-	}
 	query := parsed.Query()
 	query.Set(key, value)
 	parsed.RawQuery = query.Encode()
@@ -257,34 +266,63 @@ if token == "" {
 WriteError(w, http.StatusUnauthorized, fmt.Errorf("missing session token"))
 return
 }
-userID, expiresAt, ok, err := h.sessionManager().Validate(token)
+session, ok, err := h.sessionManager().ValidateSession(token)
 if err != nil {
-WriteError(w, http.StatusInternalServerError, err)
+if errors.Is(err, auth.ErrSessionStoreUnavailable) {
+WriteError(w, http.StatusServiceUnavailable, fmt.Errorf("session store unavailable"))
+return
+}
+writeStorageError(w, http.StatusInternalServerError, err)
 return
 }
 if !ok {
 WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid or expired session"))
 return
 }
-user, exists := h.Store.GetUser(userID)
+user, exists := h.Store.GetUser(session.UserID)
 if !exists {
 WriteError(w, http.StatusUnauthorized, fmt.Errorf("account not found"))
 return
 }
-if _, err := r.Cookie("bitriver_session"); err == nil {
-h.RefreshSessionCookie(w, r, token, expiresAt)
+if user.Deactivated() {
+_ = h.sessionManager().Revoke(token)
+WriteError(w, http.StatusUnauthorized, fmt.Errorf("account is deactivated"))
+return
 }
-WriteJSON(w, http.StatusOK, newAuthResponse(user, expiresAt))
+resp := newAuthResponse(user, session.ExpiresAt)
+if session.Impersonated() {
+admin, err := h.Impersonator(session)
+if err != nil {
+_ = h.sessionManager().Revoke(token)
+h.ClearImpersonationCookie(w, r)
+WriteError(w, http.StatusUnauthorized, err)
+return
+}
+resp.Impersonation = newImpersonationResponse(admin, session)
+} else if _, err := r.Cookie(sessionCookieName); err == nil {
+h.RefreshSessionCookie(w, r, token, session.ExpiresAt)
+}
+WriteJSON(w, http.StatusOK, resp)
 	case http.MethodDelete:
 		token := ExtractToken(r)
 		if token == "" {
 			WriteError(w, http.StatusBadRequest, fmt.Errorf("missing session token"))
 			return
 		}
+		if UsesImpersonationCookie(r) {
+			// Ending an impersonation only drops its session; the
+			// administrator's own session cookie stays signed in.
+			h.endImpersonation(w, r, token)
+			return
+		}
+		session, known, _ := h.sessionManager().ValidateSession(token)
 		if err := h.sessionManager().Revoke(token); err != nil {
-			WriteError(w, http.StatusInternalServerError, err)
+			writeStorageError(w, http.StatusInternalServerError, err)
 			return
 		}
+		if known {
+			h.auditAuth(r, auth.AuditEvent{Action: auth.AuditActionLogout, ActorID: session.UserID, UserID: session.UserID})
+		}
 		h.ClearSessionCookie(w, r)
 		w.WriteHeader(http.StatusNoContent)
 	default:
@@ -292,7 +330,24 @@ WriteJSON(w, http.StatusOK, newAuthResponse(user, expiresAt))
 	}
 }
 
+// ExtractToken returns the request's session or access token. A bearer token
+// wins, then the impersonation cookie, so an administrator acting as another
+// user is served as that user until the impersonation ends, then the regular
+// session cookie.
 func ExtractToken(r *http.Request) string {
+	if token := bearerToken(r); token != "" {
+		return token
+	}
+	if cookie, err := r.Cookie(impersonationCookieName); err == nil && cookie.Value != "" {
+		return cookie.Value
+	}
+	if cookie, err := r.Cookie(sessionCookieName); err == nil {
+		return cookie.Value
+	}
+	return ""
+}
+
+func bearerToken(r *http.Request) string {
 	header := r.Header.Get("Authorization")
 	if header != "" {
 		parts := strings.SplitN(header, " ", 2)
@@ -300,9 +355,6 @@ func ExtractToken(r *http.Request) string {
 			return strings.TrimSpace(parts[1])
 		}
 	}
-	if cookie, err := r.Cookie("bitriver_session"); err == nil {
-		return cookie.Value
-	}
 	return ""
 }
 
@@ -310,6 +362,7 @@ func ExtractToken(r *http.Request) string {
 
 type createUserRequest struct {
 	DisplayName string   `json:"displayName"`
+	Username    string   `json:"username"`
 	Email       string   `json:"email"`
 	Roles       []string `json:"roles"`
 	Password    string   `json:"password,omitempty"`
@@ -319,10 +372,22 @@ type updateUserRequest struct {
 	DisplayName *string   `json:"displayName"`
 	Email       *string   `json:"email"`
 	Roles       *[]string `json:"roles"`
+	// Active deactivates the account when false and reactivates it when
+	// true. Deactivating revokes every session the user holds.
+	Active *bool `json:"active"`
+}
+
+type updateCurrentUserRequest struct {
+	// BirthDate is a YYYY-MM-DD date that can only be set once.
+	BirthDate *string `json:"birthDate"`
+	// Username changes the user's handle, subject to the change cooldown.
+	Username *string `json:"username"`
 }
 
 type signupRequest struct {
 	DisplayName string `json:"displayName"`
+	// Username is optional; one is derived from the display name without it.
+	Username string `json:"username"`
 	Email       string `json:"email"`
 	Password    string `json:"password"`
 }
@@ -335,28 +400,60 @@ type loginRequest struct {
 type authResponse struct {
 	ExpiresAt string       `json:"expiresAt"`
 	User      userResponse `json:"user"`
+	// Impersonation is set when an administrator is acting as User.
+	Impersonation *impersonationResponse `json:"impersonation,omitempty"`
 }
 
 type userResponse struct {
 	ID          string   `json:"id"`
 	DisplayName string   `json:"displayName"`
+	Username    string   `json:"username"`
 	Email       string   `json:"email"`
 	Roles       []string `json:"roles"`
 	SelfSignup  bool     `json:"selfSignup"`
 	HasPassword bool     `json:"hasPassword"`
 	CreatedAt   string   `json:"createdAt"`
+	// BirthDate and AgeConfirmedAt are set once the user has confirmed
+	// their age for mature channels.
+	BirthDate      string  `json:"birthDate,omitempty"`
+	AgeConfirmedAt *string `json:"ageConfirmedAt,omitempty"`
+	// DeactivatedAt is set once the account has been deprovisioned.
+	DeactivatedAt *string `json:"deactivatedAt,omitempty"`
+	// UsernameChangedAt is when the username was last chosen, and
+	// UsernameGrace is set while an assigned username can still be
+	// replaced without waiting for the change cooldown.
+	UsernameChangedAt *string `json:"usernameChangedAt,omitempty"`
+	UsernameGrace     bool    `json:"usernameGrace,omitempty"`
 }
 
 func newUserResponse(user models.User) userResponse {
-	return userResponse{
-		ID:          user.ID,
-		DisplayName: user.DisplayName,
-		Email:       user.Email,
-		Roles:       append([]string{}, user.Roles...),
-		SelfSignup:  user.SelfSignup,
-		HasPassword: user.PasswordHash != "",
-		CreatedAt:   user.CreatedAt.Format(time.RFC3339Nano),
-	}
+	resp := userResponse{
+		ID:            user.ID,
+		DisplayName:   user.DisplayName,
+		Username:      user.Username,
+		Email:         user.Email,
+		Roles:         append([]string{}, user.Roles...),
+		SelfSignup:    user.SelfSignup,
+		HasPassword:   user.PasswordHash != "",
+		CreatedAt:     user.CreatedAt.Format(time.RFC3339Nano),
+		UsernameGrace: user.UsernameGrace,
+	}
+	if user.BirthDate != nil {
+		resp.BirthDate = user.BirthDate.Format(time.DateOnly)
+	}
+	if user.AgeConfirmedAt != nil {
+		confirmed := user.AgeConfirmedAt.Format(time.RFC3339Nano)
+		resp.AgeConfirmedAt = &confirmed
+	}
+	if user.DeactivatedAt != nil {
+		deactivated := user.DeactivatedAt.Format(time.RFC3339Nano)
+		resp.DeactivatedAt = &deactivated
+	}
+	if user.UsernameChangedAt != nil {
+		changed := user.UsernameChangedAt.Format(time.RFC3339Nano)
+		resp.UsernameChangedAt = &changed
+	}
+	return resp
 }
 
 func newAuthResponse(user models.User, expires time.Time) authResponse {
@@ -369,17 +466,43 @@ func newAuthResponse(user models.User, expires time.Time) authResponse {
 func (h *Handler) Users(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		if _, ok := h.requireRole(w, r, roleAdmin); !ok {
+		if _, ok := h.requirePermission(w, r, authz.UsersManage); !ok {
+			return
+		}
+		// Versioned requests always get the page envelope; the bare array
+		// is kept for unversioned clients that send no listing parameters.
+		query, paginated, err := parseUserListQuery(r.URL.Query(), requestAPIVersion(r).Versioned())
+		if err != nil {
+			WriteRequestError(w, err)
+			return
+		}
+		if paginated {
+			page, err := h.Store.ListUsersPage(query.Options)
+			if err != nil {
+				writeStorageError(w, http.StatusInternalServerError, err)
+				return
+			}
+			items := make([]userResponse, 0, len(page.Users))
+			for _, user := range page.Users {
+				items = append(items, newUserResponse(user))
+			}
+			writePage(w, query, items, page.Total)
+			return
+		}
+		users, err := h.Store.ListUsers()
+		if err != nil {
+			writeStorageError(w, http.StatusInternalServerError, err)
 			return
 		}
-		users := h.Store.ListUsers()
 		response := make([]userResponse, 0, len(users))
 		for _, user := range users {
 			response = append(response, newUserResponse(user))
 		}
+		h.markLegacyShape(w, r)
 		WriteJSON(w, http.StatusOK, response)
 	case http.MethodPost:
-		if _, ok := h.requireRole(w, r, roleAdmin); !ok {
+		actor, ok := h.requirePermission(w, r, authz.UsersManage)
+		if !ok {
 			return
 		}
 		var req createUserRequest
@@ -388,14 +511,21 @@ func (h *Handler) Users(w http.ResponseWriter, r *http.Request) {
 		}
 		user, err := h.Store.CreateUser(storage.CreateUserParams{
 			DisplayName: req.DisplayName,
+			Username:    req.Username,
 			Email:       req.Email,
 			Roles:       req.Roles,
 			Password:    req.Password,
 		})
+		if writeUsernameError(w, err) {
+			return
+		}
 		if err != nil {
-			WriteError(w, http.StatusBadRequest, err)
+			writeStorageError(w, http.StatusBadRequest, err)
 			return
 		}
+		if req.Password != "" {
+			h.auditAuth(r, auth.AuditEvent{Action: auth.AuditActionPasswordSet, ActorID: actor.ID, UserID: user.ID})
+		}
 		WriteJSON(w, http.StatusCreated, newUserResponse(user))
 	default:
 		WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodPost)
@@ -408,6 +538,59 @@ func (h *Handler) UserByID(w http.ResponseWriter, r *http.Request) {
 		WriteError(w, http.StatusNotFound, fmt.Errorf("user id missing"))
 		return
 	}
+	if id == "me/tokens" || strings.HasPrefix(id, "me/tokens/") {
+		h.userAPITokens(w, r, strings.Trim(strings.TrimPrefix(id, "me/tokens"), "/"))
+		return
+	}
+	if id == "me/password" {
+		h.changePassword(w, r)
+		return
+	}
+	if id == "me/notifications" {
+		h.userNotifications(w, r)
+		return
+	}
+	if id == "me/notification-preferences" {
+		h.notificationPreferences(w, r)
+		return
+	}
+	if id == "me/playback-preferences" {
+		h.userPlaybackPreferences(w, r)
+		return
+	}
+	if id == "me/schedule-feed" {
+		h.userScheduleFeed(w, r)
+		return
+	}
+	if id == "me/following/schedule.ics" {
+		h.followedScheduleCalendar(w, r)
+		return
+	}
+	if id == "me/recommendations" {
+		h.userRecommendations(w, r)
+		return
+	}
+	if rest, ok := strings.CutPrefix(id, "me/donation-addresses/"); ok {
+		currency, action, _ := strings.Cut(rest, "/")
+		if action == "verify" {
+			h.verifyDonationAddress(w, r, currency)
+			return
+		}
+		WriteError(w, http.StatusNotFound, fmt.Errorf("unknown donation address path"))
+		return
+	}
+	if id == "me/avatar" || id == "me/banner" {
+		h.profileImage(w, r, storage.ProfileImageKind(strings.TrimPrefix(id, "me/")))
+		return
+	}
+	if id == "me" {
+		h.currentUser(w, r)
+		return
+	}
+	if handle, ok := strings.CutPrefix(id, "by-handle/"); ok && handle != "" {
+		h.userByHandle(w, r, handle)
+		return
+	}
 
 	switch r.Method {
 	case http.MethodGet:
@@ -415,7 +598,7 @@ func (h *Handler) UserByID(w http.ResponseWriter, r *http.Request) {
 		if !ok {
 			return
 		}
-		if requester.ID != id && !requester.HasRole(roleAdmin) {
+		if requester.ID != id && !authz.Has(requester, authz.UsersManage) {
 			WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
 			return
 		}
@@ -426,13 +609,19 @@ func (h *Handler) UserByID(w http.ResponseWriter, r *http.Request) {
 		}
 		WriteJSON(w, http.StatusOK, newUserResponse(user))
 	case http.MethodPatch:
-		if _, ok := h.requireRole(w, r, roleAdmin); !ok {
+		actor, ok := h.requirePermission(w, r, authz.UsersManage)
+		if !ok {
 			return
 		}
 		var req updateUserRequest
 		if !DecodeAndValidate(w, r, &req) {
 			return
 		}
+		if req.Active != nil && !*req.Active && actor.ID == id {
+			WriteRequestError(w, ValidationError("you cannot deactivate your own account"))
+			return
+		}
+		previous, _ := h.Store.GetUser(id)
 		update := storage.UserUpdate{}
 		if req.DisplayName != nil {
 			update.DisplayName = req.DisplayName
@@ -444,22 +633,114 @@ func (h *Handler) UserByID(w http.ResponseWriter, r *http.Request) {
 			rolesCopy := append([]string{}, (*req.Roles)...)
 			update.Roles = &rolesCopy
 		}
+		if req.Active != nil {
+			deactivated := !*req.Active
+			update.Deactivated = &deactivated
+		}
 		user, err := h.Store.UpdateUser(id, update)
 		if err != nil {
-			WriteError(w, http.StatusBadRequest, err)
+			writeStorageError(w, http.StatusBadRequest, err)
 			return
 		}
+		h.invalidateChatAuthor(user.ID)
+		h.invalidateDirectoryCache(r.Context())
+		switch {
+		case !previous.Deactivated() && user.Deactivated():
+			if err := h.sessionManager().RevokeUser(user.ID); err != nil {
+				h.logger().Warn("revoke sessions of deactivated user", "user_id", user.ID, "error", err)
+			}
+			h.auditLogger().Info("audit", "action", "user.deactivate", "user_id", actor.ID, "target_user_id", user.ID)
+		case previous.Deactivated() && !user.Deactivated():
+			h.auditLogger().Info("audit", "action", "user.reactivate", "user_id", actor.ID, "target_user_id", user.ID)
+		}
 		WriteJSON(w, http.StatusOK, newUserResponse(user))
 	case http.MethodDelete:
-		if _, ok := h.requireRole(w, r, roleAdmin); !ok {
+		if _, ok := h.requirePermission(w, r, authz.UsersManage); !ok {
+			return
+		}
+		// The owned channels are cleaned up below, so list them from the
+		// primary rather than a replica that may have missed a new one.
+		r = readYourWrites(r)
+		ownedChannels, err := h.store(r).ListChannels(id, "")
+		if err != nil {
+			writeStorageError(w, http.StatusInternalServerError, err)
 			return
 		}
 		if err := h.Store.DeleteUser(id); err != nil {
-			WriteError(w, http.StatusBadRequest, err)
+			writeStorageError(w, http.StatusBadRequest, err)
 			return
 		}
+		for _, channel := range ownedChannels {
+			h.invalidateChannelCache(r.Context(), channel.ID)
+		}
+		h.invalidateChatAuthor(id)
+		h.invalidateDirectoryCache(r.Context())
 		w.WriteHeader(http.StatusNoContent)
 	default:
 		WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodPatch, http.MethodDelete)
 	}
 }
+
+// currentUser serves /api/users/me. GET returns the signed-in user and PATCH
+// lets them change their username or confirm their birth date, which can only
+// be set once and unlocks mature channels for adults.
+func (h *Handler) currentUser(w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		user, ok := h.requireAuthenticatedUser(w, r)
+		if !ok {
+			return
+		}
+		WriteJSON(w, http.StatusOK, newUserResponse(user))
+	case http.MethodPatch:
+		user, ok := h.requireAuthenticatedUser(w, r)
+		if !ok {
+			return
+		}
+		var req updateCurrentUserRequest
+		if !DecodeAndValidate(w, r, &req) {
+			return
+		}
+		if req.BirthDate == nil && req.Username == nil {
+			WriteRequestError(w, ValidationError("birthDate or username is required"))
+			return
+		}
+		var birthDate time.Time
+		if req.BirthDate != nil {
+			parsed, err := time.Parse(time.DateOnly, strings.TrimSpace(*req.BirthDate))
+			if err != nil {
+				WriteRequestError(w, ValidationError("birthDate must be a YYYY-MM-DD date"))
+				return
+			}
+			birthDate = parsed
+		}
+		updated := user
+		if req.Username != nil {
+			changed, err := h.Store.ChangeUsername(user.ID, *req.Username, h.now())
+			if writeUsernameError(w, err) {
+				return
+			}
+			if err != nil {
+				writeStorageError(w, http.StatusBadRequest, err)
+				return
+			}
+			updated = changed
+			h.invalidateChatAuthor(user.ID)
+		}
+		if req.BirthDate != nil {
+			confirmed, err := h.Store.ConfirmUserBirthDate(user.ID, birthDate)
+			if errors.Is(err, storage.ErrBirthDateAlreadySet) {
+				writeStorageError(w, http.StatusConflict, err)
+				return
+			}
+			if err != nil {
+				writeStorageError(w, http.StatusBadRequest, err)
+				return
+			}
+			updated = confirmed
+		}
+		WriteJSON(w, http.StatusOK, newUserResponse(updated))
+	default:
+		WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodPatch)
+	}
+}
@@ -0,0 +1,261 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/authz"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+type badgeDefinitionRequest struct {
+	Slug    string `json:"slug"`
+	Label   string `json:"label"`
+	IconURL string `json:"iconUrl"`
+}
+
+type badgeDefinitionUpdateRequest struct {
+	Label   *string `json:"label"`
+	IconURL *string `json:"iconUrl"`
+}
+
+type badgeDefinitionResponse struct {
+	Slug      string `json:"slug"`
+	Label     string `json:"label"`
+	IconURL   string `json:"iconUrl,omitempty"`
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+type badgeGrantResponse struct {
+	UserID      string `json:"userId"`
+	DisplayName string `json:"displayName,omitempty"`
+	Slug        string `json:"slug"`
+	GrantedBy   string `json:"grantedBy,omitempty"`
+	GrantedAt   string `json:"grantedAt"`
+}
+
+// userBadgeResponse is a global badge as shown on a public profile.
+type userBadgeResponse struct {
+	Slug    string `json:"slug"`
+	Label   string `json:"label"`
+	IconURL string `json:"iconUrl,omitempty"`
+}
+
+func newBadgeDefinitionResponse(definition models.BadgeDefinition) badgeDefinitionResponse {
+	return badgeDefinitionResponse{
+		Slug:      definition.Slug,
+		Label:     definition.Label,
+		IconURL:   definition.IconURL,
+		CreatedAt: definition.CreatedAt.Format(time.RFC3339Nano),
+		UpdatedAt: definition.UpdatedAt.Format(time.RFC3339Nano),
+	}
+}
+
+func (h *Handler) newBadgeGrantResponse(grant models.BadgeGrant) badgeGrantResponse {
+	resp := badgeGrantResponse{
+		UserID:    grant.UserID,
+		Slug:      grant.Slug,
+		GrantedBy: grant.GrantedBy,
+		GrantedAt: grant.GrantedAt.Format(time.RFC3339Nano),
+	}
+	if user, ok := h.Store.GetUser(grant.UserID); ok {
+		resp.DisplayName = user.DisplayName
+	}
+	return resp
+}
+
+// badgeError maps storage errors from the badge methods.
+func badgeError(err error) RequestError {
+	switch {
+	case errors.Is(err, storage.ErrBadgeNotFound):
+		return RequestError{Status: http.StatusNotFound, CodeVal: "badge_not_found", Message: "badge not found", Err: err}
+	case errors.Is(err, storage.ErrBadgeGrantNotFound):
+		return RequestError{Status: http.StatusNotFound, CodeVal: "badge_not_granted", Message: "the user does not hold this badge", Err: err}
+	case errors.Is(err, storage.ErrBadgeExists):
+		return RequestError{Status: http.StatusConflict, CodeVal: "badge_exists", Message: "a badge with this slug already exists", Err: err}
+	case errors.Is(err, storage.ErrBadgeInUse):
+		return RequestError{Status: http.StatusConflict, CodeVal: "badge_in_use", Message: "revoke the badge from every user before deleting it", Err: err}
+	default:
+		return RequestError{Status: http.StatusBadRequest, Err: err}
+	}
+}
+
+// userBadges returns the global badges the user holds, with their labels
+// and icons, for profile responses.
+func (h *Handler) userBadges(userID string) ([]userBadgeResponse, error) {
+	grants, err := h.Store.ListUserBadges(userID)
+	if err != nil {
+		return nil, err
+	}
+	badges := make([]userBadgeResponse, 0, len(grants))
+	if len(grants) == 0 {
+		return badges, nil
+	}
+	definitions, err := h.Store.ListBadgeDefinitions()
+	if err != nil {
+		return nil, err
+	}
+	bySlug := make(map[string]models.BadgeDefinition, len(definitions))
+	for _, definition := range definitions {
+		bySlug[definition.Slug] = definition
+	}
+	for _, grant := range grants {
+		definition, ok := bySlug[grant.Slug]
+		if !ok {
+			continue
+		}
+		badges = append(badges, userBadgeResponse{Slug: definition.Slug, Label: definition.Label, IconURL: definition.IconURL})
+	}
+	return badges, nil
+}
+
+func (h *Handler) writeBadgeDefinitions(w http.ResponseWriter) {
+	definitions, err := h.Store.ListBadgeDefinitions()
+	if err != nil {
+		writeStorageError(w, http.StatusInternalServerError, err)
+		return
+	}
+	response := make([]badgeDefinitionResponse, 0, len(definitions))
+	for _, definition := range definitions {
+		response = append(response, newBadgeDefinitionResponse(definition))
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// Badges serves GET /api/badges, the public list of global badge
+// definitions clients use to label and draw the badge slugs chat carries.
+func (h *Handler) Badges(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	h.writeBadgeDefinitions(w)
+}
+
+// AdminBadges serves /api/admin/badges for holders of platform.manage: GET
+// lists the badge definitions and POST adds one.
+func (h *Handler) AdminBadges(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodPost)
+		return
+	}
+	actor, ok := h.requirePermission(w, r, authz.PlatformManage)
+	if !ok {
+		return
+	}
+	if r.Method == http.MethodGet {
+		h.writeBadgeDefinitions(w)
+		return
+	}
+	var req badgeDefinitionRequest
+	if !DecodeAndValidate(w, r, &req) {
+		return
+	}
+	definition, err := h.Store.CreateBadgeDefinition(storage.BadgeDefinitionParams{Slug: req.Slug, Label: req.Label, IconURL: req.IconURL})
+	if err != nil {
+		WriteRequestError(w, badgeError(err))
+		return
+	}
+	h.auditLogger().Info("audit", "action", "badge.create", "actor_id", actor.ID, "badge", definition.Slug)
+	WriteJSON(w, http.StatusCreated, newBadgeDefinitionResponse(definition))
+}
+
+// AdminBadgeBySlug serves the rest of /api/admin/badges/ for holders of
+// platform.manage:
+//
+//	PATCH, DELETE /api/admin/badges/{slug}                 change or remove a definition
+//	GET           /api/admin/badges/{slug}/users           list the badge's holders
+//	PUT, DELETE   /api/admin/badges/{slug}/users/{userId}  grant or revoke the badge
+//
+// Grants and revocations drop the user from every channel's chat author
+// cache so their next message shows the change.
+func (h *Handler) AdminBadgeBySlug(w http.ResponseWriter, r *http.Request) {
+	actor, ok := h.requirePermission(w, r, authz.PlatformManage)
+	if !ok {
+		return
+	}
+	parts := strings.Split(strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/badges/"), "/"), "/")
+	slug := strings.TrimSpace(parts[0])
+	if slug == "" || len(parts) > 3 || (len(parts) > 1 && parts[1] != "users") {
+		WriteError(w, http.StatusNotFound, fmt.Errorf("unknown badges path"))
+		return
+	}
+
+	switch len(parts) {
+	case 1:
+		switch r.Method {
+		case http.MethodPatch:
+			var req badgeDefinitionUpdateRequest
+			if !DecodeAndValidate(w, r, &req) {
+				return
+			}
+			definition, err := h.Store.UpdateBadgeDefinition(slug, storage.BadgeDefinitionUpdate{Label: req.Label, IconURL: req.IconURL})
+			if err != nil {
+				WriteRequestError(w, badgeError(err))
+				return
+			}
+			h.auditLogger().Info("audit", "action", "badge.update", "actor_id", actor.ID, "badge", slug)
+			WriteJSON(w, http.StatusOK, newBadgeDefinitionResponse(definition))
+		case http.MethodDelete:
+			if err := h.Store.DeleteBadgeDefinition(slug); err != nil {
+				WriteRequestError(w, badgeError(err))
+				return
+			}
+			h.auditLogger().Info("audit", "action", "badge.delete", "actor_id", actor.ID, "badge", slug)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			WriteMethodNotAllowed(w, r, http.MethodPatch, http.MethodDelete)
+		}
+	case 2:
+		if r.Method != http.MethodGet {
+			WriteMethodNotAllowed(w, r, http.MethodGet)
+			return
+		}
+		grants, err := h.Store.ListBadgeGrants(slug)
+		if err != nil {
+			WriteRequestError(w, badgeError(err))
+			return
+		}
+		response := make([]badgeGrantResponse, 0, len(grants))
+		for _, grant := range grants {
+			response = append(response, h.newBadgeGrantResponse(grant))
+		}
+		WriteJSON(w, http.StatusOK, response)
+	default:
+		userID := strings.TrimSpace(parts[2])
+		if userID == "" {
+			WriteError(w, http.StatusNotFound, fmt.Errorf("unknown badges path"))
+			return
+		}
+		switch r.Method {
+		case http.MethodPut:
+			if _, ok := h.Store.GetUser(userID); !ok {
+				WriteError(w, http.StatusNotFound, fmt.Errorf("user %s not found", userID))
+				return
+			}
+			grant, err := h.Store.GrantBadge(userID, slug, actor.ID)
+			if err != nil {
+				WriteRequestError(w, badgeError(err))
+				return
+			}
+			h.invalidateChatAuthor(userID)
+			h.auditLogger().Info("audit", "action", "badge.grant", "actor_id", actor.ID, "badge", slug, "user_id", userID)
+			WriteJSON(w, http.StatusOK, h.newBadgeGrantResponse(grant))
+		case http.MethodDelete:
+			if err := h.Store.RevokeBadge(userID, slug); err != nil {
+				WriteRequestError(w, badgeError(err))
+				return
+			}
+			h.invalidateChatAuthor(userID)
+			h.auditLogger().Info("audit", "action", "badge.revoke", "actor_id", actor.ID, "badge", slug, "user_id", userID)
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			WriteMethodNotAllowed(w, r, http.MethodPut, http.MethodDelete)
+		}
+	}
+}
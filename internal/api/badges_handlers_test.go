@@ -0,0 +1,219 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+
+	"bitriver-live/internal/chat"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+func serveBadges(t *testing.T, serve http.HandlerFunc, actor models.User, method, path, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	var req *http.Request
+	if body != "" {
+		req = httptest.NewRequest(method, path, strings.NewReader(body))
+	} else {
+		req = httptest.NewRequest(method, path, nil)
+	}
+	rec := httptest.NewRecorder()
+	serve(rec, withUser(req, actor))
+	return rec
+}
+
+func TestAdminBadgesGrantShowsInChatAndProfile(t *testing.T) {
+	handler, store := newTestHandler(t)
+	admin, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Admin", Email: "admin@example.com", Roles: []string{"admin"}})
+	if err != nil {
+		t.Fatalf("CreateUser admin: %v", err)
+	}
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser owner: %v", err)
+	}
+	viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser viewer: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Elsewhere", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	if _, err := store.CreateChatMessage(channel.ID, viewer.ID, "hello", ""); err != nil {
+		t.Fatalf("CreateChatMessage: %v", err)
+	}
+	handler.ChatGateway = chat.NewGateway(chat.GatewayConfig{Queue: chat.NewMemoryQueue(16), Store: store})
+
+	rec := serveBadges(t, handler.AdminBadgeBySlug, admin, http.MethodPut, "/api/admin/badges/verified/users/"+viewer.ID, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 granting the badge, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var grant badgeGrantResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &grant); err != nil {
+		t.Fatalf("decode grant: %v", err)
+	}
+	if grant.UserID != viewer.ID || grant.Slug != "verified" || grant.GrantedBy != admin.ID || grant.DisplayName != "Viewer" {
+		t.Fatalf("unexpected grant %+v", grant)
+	}
+
+	rec = serveBadges(t, handler.AdminBadgeBySlug, admin, http.MethodGet, "/api/admin/badges/verified/users", "")
+	var holders []badgeGrantResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &holders); err != nil {
+		t.Fatalf("decode holders: %v", err)
+	}
+	if len(holders) != 1 || holders[0].UserID != viewer.ID {
+		t.Fatalf("expected the viewer to hold the badge, got %+v", holders)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/channels/"+channel.ID+"/chat", nil)
+	rec = httptest.NewRecorder()
+	handler.ChannelByID(rec, withUser(req, owner))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 listing chat, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var history []struct {
+		Author chat.Author `json:"author"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &history); err != nil {
+		t.Fatalf("decode chat: %v", err)
+	}
+	if len(history) != 1 || !reflect.DeepEqual(history[0].Author.Badges, []string{"verified"}) {
+		t.Fatalf("expected the global badge on a channel the viewer has no role in, got %+v", history)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/profiles/"+viewer.ID, nil)
+	rec = httptest.NewRecorder()
+	handler.ProfileByID(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 loading the profile, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var profile profileViewResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &profile); err != nil {
+		t.Fatalf("decode profile: %v", err)
+	}
+	if want := []userBadgeResponse{{Slug: "verified", Label: "Verified"}}; !reflect.DeepEqual(profile.Badges, want) {
+		t.Fatalf("expected the profile to list the badge, got %+v", profile.Badges)
+	}
+
+	rec = serveBadges(t, handler.AdminBadgeBySlug, admin, http.MethodDelete, "/api/admin/badges/verified/users/"+viewer.ID, "")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 revoking the badge, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = serveBadges(t, handler.AdminBadgeBySlug, admin, http.MethodDelete, "/api/admin/badges/verified/users/"+viewer.ID, "")
+	if rec.Code != http.StatusNotFound || decodeAPIError(t, rec.Body.Bytes()).Error.Code != "badge_not_granted" {
+		t.Fatalf("expected badge_not_granted revoking twice, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestAdminBadgesGrantInvalidatesChatAuthorCache(t *testing.T) {
+	handler, store := newTestHandler(t)
+	admin, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Admin", Email: "admin@example.com", Roles: []string{"admin"}})
+	if err != nil {
+		t.Fatalf("CreateUser admin: %v", err)
+	}
+	viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser viewer: %v", err)
+	}
+	channel, err := store.CreateChannel(admin.ID, "Cached", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	gateway := chat.NewGateway(chat.GatewayConfig{Queue: chat.NewMemoryQueue(16), Store: store})
+	handler.ChatGateway = gateway
+
+	badges := func() []string {
+		t.Helper()
+		message, err := gateway.CreateMessage(context.Background(), viewer, channel.ID, "hello", "")
+		if err != nil {
+			t.Fatalf("CreateMessage: %v", err)
+		}
+		return message.Author.Badges
+	}
+	if got := badges(); len(got) != 0 {
+		t.Fatalf("expected no badges before the grant, got %v", got)
+	}
+
+	rec := serveBadges(t, handler.AdminBadgeBySlug, admin, http.MethodPut, "/api/admin/badges/staff/users/"+viewer.ID, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 granting the badge, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := badges(); !reflect.DeepEqual(got, []string{"staff"}) {
+		t.Fatalf("expected the grant to reach the cached author, got %v", got)
+	}
+
+	rec = serveBadges(t, handler.AdminBadgeBySlug, admin, http.MethodDelete, "/api/admin/badges/staff/users/"+viewer.ID, "")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 revoking the badge, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := badges(); len(got) != 0 {
+		t.Fatalf("expected the revocation to reach the cached author, got %v", got)
+	}
+}
+
+func TestAdminBadgeDefinitions(t *testing.T) {
+	handler, store := newTestHandler(t)
+	admin, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Admin", Email: "admin@example.com", Roles: []string{"admin"}})
+	if err != nil {
+		t.Fatalf("CreateUser admin: %v", err)
+	}
+	viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser viewer: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		serve  http.HandlerFunc
+		method string
+		path   string
+		body   string
+		status int
+		code   string
+	}{
+		{"create", handler.AdminBadges, http.MethodPost, "/api/admin/badges", `{"slug":"partner","label":"Partner","iconUrl":"https://cdn.example.com/partner.png"}`, http.StatusCreated, ""},
+		{"duplicate", handler.AdminBadges, http.MethodPost, "/api/admin/badges", `{"slug":"partner","label":"Again"}`, http.StatusConflict, "badge_exists"},
+		{"bad slug", handler.AdminBadges, http.MethodPost, "/api/admin/badges", `{"slug":"Not A Slug!","label":"Bad"}`, http.StatusBadRequest, ""},
+		{"reserved slug", handler.AdminBadges, http.MethodPost, "/api/admin/badges", `{"slug":"moderator","label":"Mod"}`, http.StatusBadRequest, ""},
+		{"bad icon", handler.AdminBadges, http.MethodPost, "/api/admin/badges", `{"slug":"icon","label":"Icon","iconUrl":"javascript:alert(1)"}`, http.StatusBadRequest, ""},
+		{"relabel", handler.AdminBadgeBySlug, http.MethodPatch, "/api/admin/badges/partner", `{"label":"Partnered"}`, http.StatusOK, ""},
+		{"relabel missing", handler.AdminBadgeBySlug, http.MethodPatch, "/api/admin/badges/ghost", `{"label":"Ghost"}`, http.StatusNotFound, "badge_not_found"},
+		{"grant undefined", handler.AdminBadgeBySlug, http.MethodPut, "/api/admin/badges/ghost/users/" + viewer.ID, "", http.StatusNotFound, "badge_not_found"},
+		{"grant", handler.AdminBadgeBySlug, http.MethodPut, "/api/admin/badges/partner/users/" + viewer.ID, "", http.StatusOK, ""},
+		{"delete in use", handler.AdminBadgeBySlug, http.MethodDelete, "/api/admin/badges/partner", "", http.StatusConflict, "badge_in_use"},
+		{"revoke", handler.AdminBadgeBySlug, http.MethodDelete, "/api/admin/badges/partner/users/" + viewer.ID, "", http.StatusNoContent, ""},
+		{"delete", handler.AdminBadgeBySlug, http.MethodDelete, "/api/admin/badges/partner", "", http.StatusNoContent, ""},
+		{"unknown path", handler.AdminBadgeBySlug, http.MethodGet, "/api/admin/badges/partner/holders", "", http.StatusNotFound, ""},
+	}
+	for _, tc := range cases {
+		rec := serveBadges(t, tc.serve, admin, tc.method, tc.path, tc.body)
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+		if tc.code != "" {
+			if got := decodeAPIError(t, rec.Body.Bytes()).Error.Code; got != tc.code {
+				t.Fatalf("%s: expected error code %q, got %q", tc.name, tc.code, got)
+			}
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handler.Badges(rec, httptest.NewRequest(http.MethodGet, "/api/badges", nil))
+	var definitions []badgeDefinitionResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &definitions); err != nil {
+		t.Fatalf("decode definitions: %v", err)
+	}
+	slugs := make([]string, 0, len(definitions))
+	for _, definition := range definitions {
+		slugs = append(slugs, definition.Slug)
+	}
+	if want := []string{"founder", "staff", "verified"}; !reflect.DeepEqual(slugs, want) {
+		t.Fatalf("expected only the default badges after the delete, got %v", slugs)
+	}
+}
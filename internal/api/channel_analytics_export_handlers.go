@@ -0,0 +1,229 @@
+package api
+
+import (
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+const (
+	defaultAnalyticsExportLimit  = 10
+	defaultAnalyticsExportWindow = time.Hour
+	// defaultAnalyticsExportDays is how far back an export reaches when the
+	// request names no start.
+	defaultAnalyticsExportDays = 30
+	// analyticsExportFlushEvery is how many CSV rows are buffered before
+	// they are flushed to the client.
+	analyticsExportFlushEvery = 100
+	analyticsExportDateLayout = "2006-01-02"
+)
+
+// Datasets a channel analytics export can return.
+const (
+	analyticsDatasetSessions = "sessions"
+	analyticsDatasetFollows  = "follows"
+	analyticsDatasetRevenue  = "revenue"
+)
+
+var analyticsExportHeaders = map[string][]string{
+	analyticsDatasetSessions: {"session_id", "started_at", "ended_at", "duration_seconds", "peak_viewers", "language"},
+	analyticsDatasetFollows:  {"date", "follows"},
+	analyticsDatasetRevenue:  {"date", "currency", "tips", "tip_amount", "subscriptions", "subscription_amount", "total_amount"},
+}
+
+// analyticsExportRate returns the configured exports-per-window limit,
+// falling back to the defaults for unset fields.
+func (h *Handler) analyticsExportRate() (int, time.Duration) {
+	limit := h.AnalyticsExportLimit
+	if limit <= 0 {
+		limit = defaultAnalyticsExportLimit
+	}
+	window := h.AnalyticsExportWindow
+	if window <= 0 {
+		window = defaultAnalyticsExportWindow
+	}
+	return limit, window
+}
+
+// parseAnalyticsBound reads a from or to query value, either a date or an
+// RFC 3339 timestamp. A date names a whole UTC day, so as an end bound it
+// covers that day.
+func parseAnalyticsBound(value string, end bool) (time.Time, error) {
+	if day, err := time.Parse(analyticsExportDateLayout, value); err == nil {
+		if end {
+			day = day.AddDate(0, 0, 1)
+		}
+		return day.UTC(), nil
+	}
+	ts, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return ts.UTC(), nil
+}
+
+// analyticsExportRange reads the [from, to) range of an export request,
+// defaulting to the last defaultAnalyticsExportDays days.
+func analyticsExportRange(r *http.Request, now time.Time) (time.Time, time.Time, error) {
+	query := r.URL.Query()
+	to := now.UTC()
+	if value := strings.TrimSpace(query.Get("to")); value != "" {
+		parsed, err := parseAnalyticsBound(value, true)
+		if err != nil {
+			return time.Time{}, time.Time{}, ValidationError("to must be a date (YYYY-MM-DD) or an RFC 3339 timestamp")
+		}
+		to = parsed
+	}
+	from := to.AddDate(0, 0, -defaultAnalyticsExportDays)
+	if value := strings.TrimSpace(query.Get("from")); value != "" {
+		parsed, err := parseAnalyticsBound(value, false)
+		if err != nil {
+			return time.Time{}, time.Time{}, ValidationError("from must be a date (YYYY-MM-DD) or an RFC 3339 timestamp")
+		}
+		from = parsed
+	}
+	if err := storage.ValidateAnalyticsRange(from, to); err != nil {
+		return time.Time{}, time.Time{}, ValidationError("the range must end after it starts and span at most one year")
+	}
+	return from, to, nil
+}
+
+// analyticsExportFilename names an export after the channel, dataset, and
+// the days it covers.
+func analyticsExportFilename(channelID, dataset string, from, to time.Time) string {
+	last := to.Add(-time.Nanosecond)
+	return fmt.Sprintf("%s-%s-%s-to-%s.csv", channelID, dataset, from.Format(analyticsExportDateLayout), last.Format(analyticsExportDateLayout))
+}
+
+// handleChannelAnalytics serves GET /api/channels/{id}/analytics/export,
+// streaming one analytics dataset of the channel as CSV to its owner or an
+// administrator. Rows are flushed as they are written so a year of data is
+// never buffered in full.
+func (h *Handler) handleChannelAnalytics(channel models.Channel, remaining []string, w http.ResponseWriter, r *http.Request) {
+	if len(remaining) != 1 || remaining[0] != "export" {
+		WriteError(w, http.StatusNotFound, fmt.Errorf("unknown analytics path"))
+		return
+	}
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	user, ok := h.ensureChannelAccess(w, r, channel)
+	if !ok {
+		return
+	}
+	dataset := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("dataset")))
+	header, known := analyticsExportHeaders[dataset]
+	if !known {
+		WriteRequestError(w, ValidationError("dataset must be one of sessions, follows, or revenue"))
+		return
+	}
+	now := h.now()
+	from, to, err := analyticsExportRange(r, now)
+	if err != nil {
+		var reqErr RequestError
+		if errors.As(err, &reqErr) {
+			WriteRequestError(w, reqErr)
+			return
+		}
+		writeStorageError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	limit, window := h.analyticsExportRate()
+	if allowed, retry := h.analyticsExports.allow(channel.ID, limit, window, now); !allowed {
+		seconds := int((retry + time.Second - 1) / time.Second)
+		if seconds < 1 {
+			seconds = 1
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		WriteRequestError(w, RequestError{Status: http.StatusTooManyRequests, CodeVal: "rate_limited", Message: fmt.Sprintf("a channel can export analytics %d times every %s", limit, window)})
+		return
+	}
+
+	w.Header().Set("Content-Type", "text/csv; charset=utf-8")
+	w.Header().Set("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, analyticsExportFilename(channel.ID, dataset, from, to)))
+	w.Header().Set("Cache-Control", "no-store")
+	w.WriteHeader(http.StatusOK)
+
+	flusher, _ := w.(http.Flusher)
+	writer := csv.NewWriter(w)
+	written := 0
+	write := func(record []string) error {
+		for i := range record {
+			record[i] = spreadsheetSafe(record[i])
+		}
+		if err := writer.Write(record); err != nil {
+			return err
+		}
+		written++
+		if written%analyticsExportFlushEvery == 0 {
+			writer.Flush()
+			if flusher != nil {
+				flusher.Flush()
+			}
+			return writer.Error()
+		}
+		return nil
+	}
+	if err := writer.Write(header); err != nil {
+		return
+	}
+
+	switch dataset {
+	case analyticsDatasetSessions:
+		err = h.Store.ExportChannelSessions(channel.ID, from, to, func(row storage.ChannelSessionExportRow) error {
+			end := now
+			endedAt := ""
+			if row.EndedAt != nil {
+				end = *row.EndedAt
+				endedAt = row.EndedAt.Format(time.RFC3339)
+			}
+			duration := int64(0)
+			if end.After(row.StartedAt) {
+				duration = int64(end.Sub(row.StartedAt) / time.Second)
+			}
+			return write([]string{
+				row.SessionID,
+				row.StartedAt.Format(time.RFC3339),
+				endedAt,
+				strconv.FormatInt(duration, 10),
+				strconv.Itoa(row.PeakConcurrent),
+				row.Language,
+			})
+		})
+	case analyticsDatasetFollows:
+		err = h.Store.ExportChannelFollows(channel.ID, from, to, func(row storage.ChannelFollowExportRow) error {
+			return write([]string{row.Day.Format(analyticsExportDateLayout), strconv.Itoa(row.Follows)})
+		})
+	case analyticsDatasetRevenue:
+		err = h.Store.ExportChannelRevenue(channel.ID, from, to, func(row storage.ChannelRevenueExportRow) error {
+			return write([]string{
+				row.Day.Format(analyticsExportDateLayout),
+				row.Currency,
+				strconv.Itoa(row.Tips),
+				row.TipAmount.DecimalString(),
+				strconv.Itoa(row.Subscriptions),
+				row.SubscriptionAmount.DecimalString(),
+				row.TipAmount.Add(row.SubscriptionAmount).DecimalString(),
+			})
+		})
+	}
+	writer.Flush()
+	if flusher != nil {
+		flusher.Flush()
+	}
+	if err == nil {
+		err = writer.Error()
+	}
+	if err != nil {
+		h.logger().Error("export channel analytics", "channel_id", channel.ID, "user_id", user.ID, "dataset", dataset, "rows", written, "error", err)
+	}
+}
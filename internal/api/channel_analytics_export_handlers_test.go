@@ -0,0 +1,189 @@
+package api
+
+import (
+	"encoding/csv"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+func newAnalyticsExportFixture(t *testing.T) (*Handler, *storage.Storage, models.User, models.Channel) {
+	t.Helper()
+	handler, store := newTestHandler(t)
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser owner: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Stats", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	return handler, store, owner, channel
+}
+
+func exportChannelAnalytics(handler *Handler, user models.User, channelID, query string) *httptest.ResponseRecorder {
+	req := withUser(httptest.NewRequest(http.MethodGet, "/api/channels/"+channelID+"/analytics/export?"+query, nil), user)
+	rec := httptest.NewRecorder()
+	handler.ChannelByID(rec, req)
+	return rec
+}
+
+func readAnalyticsCSV(t *testing.T, rec *httptest.ResponseRecorder) [][]string {
+	t.Helper()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/csv") {
+		t.Fatalf("expected CSV content type, got %q", ct)
+	}
+	records, err := csv.NewReader(rec.Body).ReadAll()
+	if err != nil {
+		t.Fatalf("parse CSV: %v", err)
+	}
+	return records
+}
+
+func TestChannelAnalyticsExportDatasets(t *testing.T) {
+	handler, store, owner, channel := newAnalyticsExportFixture(t)
+	fan, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Fan", Email: "fan@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser fan: %v", err)
+	}
+	if _, err := store.StartStream(channel.ID, []string{"720p"}); err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	ended, err := store.StopStream(channel.ID, 42)
+	if err != nil {
+		t.Fatalf("StopStream: %v", err)
+	}
+	if err := store.FollowChannel(fan.ID, channel.ID); err != nil {
+		t.Fatalf("FollowChannel: %v", err)
+	}
+	tips := []storage.CreateTipParams{
+		{ChannelID: channel.ID, FromUserID: fan.ID, Amount: models.MustParseMoney("1.5"), Currency: "usd", Provider: "stripe", Reference: "tip-1"},
+		{ChannelID: channel.ID, FromUserID: fan.ID, Amount: models.MustParseMoney("2"), Currency: "USD", Provider: "stripe", Reference: "tip-2"},
+		{ChannelID: channel.ID, FromUserID: fan.ID, Amount: models.MustParseMoney("3"), Currency: "=CMD", Provider: "wallet", Reference: "tip-3"},
+	}
+	for _, params := range tips {
+		if _, err := store.CreateTip(params); err != nil {
+			t.Fatalf("CreateTip %s: %v", params.Reference, err)
+		}
+	}
+	if _, err := store.CreateSubscription(storage.CreateSubscriptionParams{ChannelID: channel.ID, UserID: fan.ID, Tier: "tier1", Provider: "stripe", Reference: "sub-1", Amount: models.MustParseMoney("4.99"), Currency: "USD", Duration: time.Hour}); err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+	now := time.Now().UTC()
+	today := now.Format("2006-01-02")
+	query := "from=" + now.AddDate(0, 0, -1).Format("2006-01-02") + "&to=" + today + "&dataset="
+
+	rec := exportChannelAnalytics(handler, owner, channel.ID, query+"sessions")
+	records := readAnalyticsCSV(t, rec)
+	if len(records) != 2 || strings.Join(records[0], ",") != "session_id,started_at,ended_at,duration_seconds,peak_viewers,language" {
+		t.Fatalf("unexpected sessions export %v", records)
+	}
+	if row := records[1]; row[0] != ended.ID || row[2] == "" || row[4] != "42" {
+		t.Fatalf("unexpected session row %v", row)
+	}
+	expected := fmt.Sprintf(`attachment; filename="%s-sessions-%s-to-%s.csv"`, channel.ID, now.AddDate(0, 0, -1).Format("2006-01-02"), today)
+	if disposition := rec.Header().Get("Content-Disposition"); disposition != expected {
+		t.Fatalf("expected disposition %q, got %q", expected, disposition)
+	}
+
+	records = readAnalyticsCSV(t, exportChannelAnalytics(handler, owner, channel.ID, query+"follows"))
+	if len(records) != 2 || records[1][0] != today || records[1][1] != "1" {
+		t.Fatalf("unexpected follows export %v", records)
+	}
+
+	records = readAnalyticsCSV(t, exportChannelAnalytics(handler, owner, channel.ID, query+"revenue"))
+	if len(records) != 3 {
+		t.Fatalf("expected one row per currency, got %v", records)
+	}
+	if got := strings.Join(records[1], ","); got != today+",'=CMD,1,3,0,0,3" {
+		t.Fatalf("expected the formula-like currency escaped, got %q", got)
+	}
+	if got := strings.Join(records[2], ","); got != today+",USD,2,3.5,1,4.99,8.49" {
+		t.Fatalf("unexpected USD row %q", got)
+	}
+}
+
+func TestChannelAnalyticsExportValidatesRange(t *testing.T) {
+	handler, _, owner, channel := newAnalyticsExportFixture(t)
+	cases := []string{
+		"dataset=follows&from=2024-01-01&to=2025-01-02",
+		"dataset=follows&from=2024-02-01&to=2024-01-01",
+		"dataset=follows&from=yesterday",
+		"dataset=clicks",
+		"",
+	}
+	for _, query := range cases {
+		rec := exportChannelAnalytics(handler, owner, channel.ID, query)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%q: expected 400, got %d: %s", query, rec.Code, rec.Body.String())
+		}
+	}
+	rec := exportChannelAnalytics(handler, owner, channel.ID, "dataset=follows&from=2024-01-01&to=2024-12-31")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected a full year to be accepted, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestChannelAnalyticsExportFlushesWhileStreaming(t *testing.T) {
+	handler, store, owner, channel := newAnalyticsExportFixture(t)
+	for i := 0; i < analyticsExportFlushEvery+1; i++ {
+		if _, err := store.StartStream(channel.ID, nil); err != nil {
+			t.Fatalf("StartStream %d: %v", i, err)
+		}
+		if _, err := store.StopStream(channel.ID, i); err != nil {
+			t.Fatalf("StopStream %d: %v", i, err)
+		}
+	}
+	rec := exportChannelAnalytics(handler, owner, channel.ID, "dataset=sessions")
+	if !rec.Flushed {
+		t.Fatal("expected export to flush while streaming")
+	}
+	if records := readAnalyticsCSV(t, rec); len(records) != analyticsExportFlushEvery+2 {
+		t.Fatalf("expected header plus %d rows, got %d", analyticsExportFlushEvery+1, len(records))
+	}
+}
+
+func TestChannelAnalyticsExportRateLimitsPerChannel(t *testing.T) {
+	handler, store, owner, channel := newAnalyticsExportFixture(t)
+	handler.AnalyticsExportLimit = 2
+	other, err := store.CreateChannel(owner.ID, "Other", "music", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	for i := 0; i < 2; i++ {
+		if rec := exportChannelAnalytics(handler, owner, channel.ID, "dataset=follows"); rec.Code != http.StatusOK {
+			t.Fatalf("export %d: expected 200, got %d", i, rec.Code)
+		}
+	}
+	rec := exportChannelAnalytics(handler, owner, channel.ID, "dataset=follows")
+	if rec.Code != http.StatusTooManyRequests || rec.Header().Get("Retry-After") == "" {
+		t.Fatalf("expected 429 with Retry-After, got %d %v", rec.Code, rec.Header())
+	}
+	if rec := exportChannelAnalytics(handler, owner, other.ID, "dataset=follows"); rec.Code != http.StatusOK {
+		t.Fatalf("expected another channel to keep its own budget, got %d", rec.Code)
+	}
+}
+
+func TestChannelAnalyticsExportRequiresChannelManager(t *testing.T) {
+	handler, store, _, channel := newAnalyticsExportFixture(t)
+	viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser viewer: %v", err)
+	}
+	rec := exportChannelAnalytics(handler, viewer, channel.ID, "dataset=revenue")
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a viewer, got %d", rec.Code)
+	}
+	if strings.Contains(rec.Header().Get("Content-Type"), "text/csv") {
+		t.Fatal("expected no CSV for a rejected export")
+	}
+}
@@ -0,0 +1,297 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"sync"
+	"time"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+const (
+	// dashboardRecordingCount is how many of the newest recordings the
+	// dashboard lists.
+	dashboardRecordingCount = 5
+	// dashboardWindow is the period follower and revenue changes are
+	// reported over.
+	dashboardWindow = 7 * 24 * time.Hour
+	// dashboardSectionUnavailable marks a section whose data could not be
+	// loaded. The rest of the dashboard is still returned.
+	dashboardSectionUnavailable = "unavailable"
+)
+
+type channelDashboardResponse struct {
+	Channel channelResponse           `json:"channel"`
+	Ingest  dashboardIngestResponse   `json:"ingest"`
+	Session *dashboardSessionResponse `json:"session"`
+	// Recordings through Uploads carry Error instead of their data when
+	// loading them failed.
+	Recordings  dashboardRecordingsResponse `json:"recordings"`
+	Followers   dashboardFollowersResponse  `json:"followers"`
+	Revenue     *dashboardRevenueResponse   `json:"revenue,omitempty"`
+	Reports     dashboardReportsResponse    `json:"reports"`
+	Uploads     dashboardUploadsResponse    `json:"uploads"`
+	GeneratedAt string                      `json:"generatedAt"`
+}
+
+type dashboardIngestResponse struct {
+	StreamState       string                 `json:"streamState"`
+	StreamKeyHint     string                 `json:"streamKeyHint,omitempty"`
+	RecordingPolicy   string                 `json:"recordingPolicy"`
+	DefaultRenditions []string               `json:"defaultRenditions"`
+	TranscodeLimits   models.TranscodeLimits `json:"transcodeLimits"`
+}
+
+type dashboardSessionResponse struct {
+	ID             string   `json:"id"`
+	StartedAt      string   `json:"startedAt"`
+	StreamState    string   `json:"streamState"`
+	ReceivingMedia bool     `json:"receivingMedia"`
+	InterruptedAt  *string  `json:"interruptedAt,omitempty"`
+	CurrentViewers int      `json:"currentViewers"`
+	PeakViewers    int      `json:"peakViewers"`
+	Renditions     []string `json:"renditions"`
+}
+
+type dashboardRecordingResponse struct {
+	ID              string  `json:"id"`
+	Title           string  `json:"title"`
+	Status          string  `json:"status"`
+	DurationSeconds int     `json:"durationSeconds"`
+	CreatedAt       string  `json:"createdAt"`
+	PublishedAt     *string `json:"publishedAt,omitempty"`
+}
+
+type dashboardRecordingsResponse struct {
+	Items []dashboardRecordingResponse `json:"items"`
+	Error string                       `json:"error,omitempty"`
+}
+
+type dashboardFollowersResponse struct {
+	Total int `json:"total"`
+	// Gained counts the current followers who followed within the last
+	// seven days.
+	Gained int    `json:"gained7d"`
+	Error  string `json:"error,omitempty"`
+}
+
+type dashboardRevenueTotalResponse struct {
+	Currency           string       `json:"currency"`
+	Tips               int          `json:"tips"`
+	TipAmount          models.Money `json:"tipAmount"`
+	Subscriptions      int          `json:"subscriptions"`
+	SubscriptionAmount models.Money `json:"subscriptionAmount"`
+}
+
+type dashboardRevenueResponse struct {
+	// Totals are the last seven days of tips and new subscriptions, one
+	// entry per currency.
+	Totals []dashboardRevenueTotalResponse `json:"totals7d"`
+	Error  string                          `json:"error,omitempty"`
+}
+
+type dashboardReportsResponse struct {
+	Open  int    `json:"open"`
+	Error string `json:"error,omitempty"`
+}
+
+type dashboardUploadsResponse struct {
+	Pending []uploadResponse `json:"pending"`
+	Error   string           `json:"error,omitempty"`
+}
+
+// recordingDashboardStatus summarises where a recording stands for its
+// creator.
+func recordingDashboardStatus(recording models.Recording) string {
+	switch {
+	case recording.PublishedAt != nil:
+		return "published"
+	case recording.OverQuota:
+		return "over_quota"
+	default:
+		return "unpublished"
+	}
+}
+
+// handleChannelDashboard serves GET /api/channels/{id}/dashboard, everything
+// the creator dashboard shows in one response. The channel owner,
+// moderators, and admins may read it; revenue is only included for those who
+// may view the channel's monetization, and the stream key hint only for
+// those who may see channel details. The sections are loaded concurrently,
+// and one that fails to load carries an error marker instead of failing the
+// response.
+func (h *Handler) handleChannelDashboard(channel models.Channel, remaining []string, w http.ResponseWriter, r *http.Request) {
+	if len(remaining) != 0 {
+		WriteError(w, http.StatusNotFound, fmt.Errorf("unknown dashboard path"))
+		return
+	}
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	actor, ok := h.requireAuthenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	if !canManageChannel(actor, channel) && !h.canModerateChannel(actor, channel) {
+		WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
+		return
+	}
+
+	now := h.now()
+	details := canViewChannelDetails(actor, channel)
+	session, live := h.Store.CurrentStreamSession(channel.ID)
+	resp := channelDashboardResponse{
+		Channel: buildChannelResponse(channel, details),
+		Ingest: dashboardIngestResponse{
+			StreamState:       h.streamState(channel, session, live),
+			RecordingPolicy:   channel.RecordingPolicy,
+			DefaultRenditions: h.srsRenditions(),
+			TranscodeLimits:   h.TranscodeLimits.WithOverride(channel.TranscodeLimits),
+		},
+		GeneratedAt: now.Format(time.RFC3339Nano),
+	}
+	if resp.Ingest.RecordingPolicy == "" {
+		resp.Ingest.RecordingPolicy = models.RecordingPolicyManual
+	}
+	if details {
+		resp.Ingest.StreamKeyHint = channel.StreamKeyHint
+	}
+	if live {
+		tracker := h.srsTracker()
+		resp.Session = &dashboardSessionResponse{
+			ID:             session.ID,
+			StartedAt:      session.StartedAt.Format(time.RFC3339Nano),
+			StreamState:    resp.Ingest.StreamState,
+			ReceivingMedia: session.ReceivingMedia,
+			CurrentViewers: tracker.current(channel.ID),
+			PeakViewers:    max(tracker.peak(channel.ID), session.PeakConcurrent),
+			Renditions:     append([]string{}, session.Renditions...),
+		}
+		if session.InterruptedAt != nil {
+			interrupted := session.InterruptedAt.Format(time.RFC3339Nano)
+			resp.Session.InterruptedAt = &interrupted
+		}
+	}
+
+	since := now.Add(-dashboardWindow)
+	sections := []func(){
+		func() { resp.Recordings = h.dashboardRecordings(channel) },
+		func() { resp.Followers = h.dashboardFollowers(channel, since, now) },
+		func() { resp.Reports = h.dashboardReports(channel) },
+		func() { resp.Uploads = h.dashboardUploads(channel) },
+	}
+	if canViewChannelMonetization(actor, channel) {
+		resp.Revenue = &dashboardRevenueResponse{}
+		sections = append(sections, func() { *resp.Revenue = h.dashboardRevenue(channel, since, now) })
+	}
+	// Each section writes only its own field, so they need no locking.
+	var wg sync.WaitGroup
+	for _, load := range sections {
+		wg.Add(1)
+		go func(load func()) {
+			defer wg.Done()
+			load()
+		}(load)
+	}
+	wg.Wait()
+
+	w.Header().Set("Cache-Control", "no-store")
+	WriteJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) dashboardRecordings(channel models.Channel) dashboardRecordingsResponse {
+	recordings, err := h.Store.ListRecordings(channel.ID, true)
+	if err != nil {
+		h.logger().Warn("dashboard recordings unavailable", "channel_id", channel.ID, "error", err)
+		return dashboardRecordingsResponse{Error: dashboardSectionUnavailable}
+	}
+	sort.SliceStable(recordings, func(i, j int) bool {
+		return recordings[i].CreatedAt.After(recordings[j].CreatedAt)
+	})
+	if len(recordings) > dashboardRecordingCount {
+		recordings = recordings[:dashboardRecordingCount]
+	}
+	items := make([]dashboardRecordingResponse, 0, len(recordings))
+	for _, recording := range recordings {
+		item := dashboardRecordingResponse{
+			ID:              recording.ID,
+			Title:           recording.Title,
+			Status:          recordingDashboardStatus(recording),
+			DurationSeconds: recording.DurationSeconds,
+			CreatedAt:       recording.CreatedAt.Format(time.RFC3339Nano),
+		}
+		if recording.PublishedAt != nil {
+			published := recording.PublishedAt.Format(time.RFC3339Nano)
+			item.PublishedAt = &published
+		}
+		items = append(items, item)
+	}
+	return dashboardRecordingsResponse{Items: items}
+}
+
+func (h *Handler) dashboardFollowers(channel models.Channel, since, now time.Time) dashboardFollowersResponse {
+	resp := dashboardFollowersResponse{Total: h.Store.CountFollowers(channel.ID)}
+	err := h.Store.ExportChannelFollows(channel.ID, since, now, func(row storage.ChannelFollowExportRow) error {
+		resp.Gained += row.Follows
+		return nil
+	})
+	if err != nil {
+		h.logger().Warn("dashboard followers unavailable", "channel_id", channel.ID, "error", err)
+		return dashboardFollowersResponse{Error: dashboardSectionUnavailable}
+	}
+	return resp
+}
+
+func (h *Handler) dashboardRevenue(channel models.Channel, since, now time.Time) dashboardRevenueResponse {
+	totals := make(map[string]*dashboardRevenueTotalResponse)
+	err := h.Store.ExportChannelRevenue(channel.ID, since, now, func(row storage.ChannelRevenueExportRow) error {
+		total, ok := totals[row.Currency]
+		if !ok {
+			total = &dashboardRevenueTotalResponse{Currency: row.Currency}
+			totals[row.Currency] = total
+		}
+		total.Tips += row.Tips
+		total.TipAmount = total.TipAmount.Add(row.TipAmount)
+		total.Subscriptions += row.Subscriptions
+		total.SubscriptionAmount = total.SubscriptionAmount.Add(row.SubscriptionAmount)
+		return nil
+	})
+	if err != nil {
+		h.logger().Warn("dashboard revenue unavailable", "channel_id", channel.ID, "error", err)
+		return dashboardRevenueResponse{Error: dashboardSectionUnavailable}
+	}
+	resp := dashboardRevenueResponse{Totals: make([]dashboardRevenueTotalResponse, 0, len(totals))}
+	for _, total := range totals {
+		resp.Totals = append(resp.Totals, *total)
+	}
+	sort.Slice(resp.Totals, func(i, j int) bool { return resp.Totals[i].Currency < resp.Totals[j].Currency })
+	return resp
+}
+
+func (h *Handler) dashboardReports(channel models.Channel) dashboardReportsResponse {
+	reports, err := h.Store.ListChatReports(channel.ID, false)
+	if err != nil {
+		h.logger().Warn("dashboard reports unavailable", "channel_id", channel.ID, "error", err)
+		return dashboardReportsResponse{Error: dashboardSectionUnavailable}
+	}
+	return dashboardReportsResponse{Open: len(reports)}
+}
+
+func (h *Handler) dashboardUploads(channel models.Channel) dashboardUploadsResponse {
+	uploads, err := h.Store.ListUploads(channel.ID)
+	if err != nil {
+		h.logger().Warn("dashboard uploads unavailable", "channel_id", channel.ID, "error", err)
+		return dashboardUploadsResponse{Error: dashboardSectionUnavailable}
+	}
+	resp := dashboardUploadsResponse{Pending: make([]uploadResponse, 0)}
+	for _, upload := range uploads {
+		if upload.Status == "pending" || upload.Status == "processing" {
+			resp.Pending = append(resp.Pending, newUploadResponse(upload))
+		}
+	}
+	return resp
+}
@@ -0,0 +1,241 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+type dashboardFixture struct {
+	handler   *Handler
+	store     *storage.Storage
+	owner     models.User
+	moderator models.User
+	admin     models.User
+	viewer    models.User
+	channel   models.Channel
+}
+
+func newDashboardFixture(t *testing.T) dashboardFixture {
+	t.Helper()
+	handler, store := newTestHandler(t)
+	f := dashboardFixture{handler: handler, store: store}
+	var err error
+	for _, user := range []struct {
+		dest  *models.User
+		name  string
+		roles []string
+	}{
+		{&f.owner, "Owner", []string{"creator"}},
+		{&f.moderator, "Moderator", nil},
+		{&f.admin, "Admin", []string{"admin"}},
+		{&f.viewer, "Viewer", nil},
+	} {
+		*user.dest, err = store.CreateUser(storage.CreateUserParams{DisplayName: user.name, Email: strings.ToLower(user.name) + "@example.com", Roles: user.roles})
+		if err != nil {
+			t.Fatalf("CreateUser %s: %v", user.name, err)
+		}
+	}
+	f.channel, err = store.CreateChannel(f.owner.ID, "Dashboard", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	if _, err := store.GrantChannelModerator(f.channel.ID, f.moderator.ID, f.owner.ID, nil); err != nil {
+		t.Fatalf("GrantChannelModerator: %v", err)
+	}
+
+	for i := 0; i < dashboardRecordingCount+1; i++ {
+		if _, err := store.StartStream(f.channel.ID, []string{"720p"}); err != nil {
+			t.Fatalf("StartStream: %v", err)
+		}
+		if _, err := store.StopStream(f.channel.ID, 10); err != nil {
+			t.Fatalf("StopStream: %v", err)
+		}
+	}
+	if _, err := store.StartStream(f.channel.ID, []string{"1080p", "720p"}); err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	if err := store.FollowChannel(f.viewer.ID, f.channel.ID); err != nil {
+		t.Fatalf("FollowChannel: %v", err)
+	}
+	tips := []storage.CreateTipParams{
+		{ChannelID: f.channel.ID, FromUserID: f.viewer.ID, Amount: models.MustParseMoney("1.5"), Currency: "USD", Provider: "stripe", Reference: "tip-1"},
+		{ChannelID: f.channel.ID, FromUserID: f.viewer.ID, Amount: models.MustParseMoney("2"), Currency: "USD", Provider: "stripe", Reference: "tip-2"},
+	}
+	for _, params := range tips {
+		if _, err := store.CreateTip(params); err != nil {
+			t.Fatalf("CreateTip: %v", err)
+		}
+	}
+	if _, err := store.CreateSubscription(storage.CreateSubscriptionParams{ChannelID: f.channel.ID, UserID: f.viewer.ID, Tier: "tier1", Provider: "stripe", Reference: "sub-1", Amount: models.MustParseMoney("4.99"), Currency: "USD", Duration: time.Hour}); err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+	if _, err := store.CreateChatReport(f.channel.ID, f.viewer.ID, f.owner.ID, "spam", "", ""); err != nil {
+		t.Fatalf("CreateChatReport: %v", err)
+	}
+	if _, err := store.CreateUpload(storage.CreateUploadParams{ChannelID: f.channel.ID, Title: "Pending", Filename: "vod.mp4"}); err != nil {
+		t.Fatalf("CreateUpload: %v", err)
+	}
+	return f
+}
+
+func (f dashboardFixture) get(t *testing.T, user *models.User) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/channels/"+f.channel.ID+"/dashboard", nil)
+	if user != nil {
+		req = withUser(req, *user)
+	}
+	rec := httptest.NewRecorder()
+	f.handler.ChannelByID(rec, req)
+	return rec
+}
+
+func decodeDashboard(t *testing.T, rec *httptest.ResponseRecorder) channelDashboardResponse {
+	t.Helper()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp channelDashboardResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode dashboard: %v", err)
+	}
+	return resp
+}
+
+func TestChannelDashboardComposesSections(t *testing.T) {
+	f := newDashboardFixture(t)
+	resp := decodeDashboard(t, f.get(t, &f.owner))
+
+	if resp.Channel.ID != f.channel.ID || resp.Channel.LiveState != "live" {
+		t.Fatalf("unexpected channel %+v", resp.Channel)
+	}
+	if resp.Ingest.StreamState != streamStateLive || resp.Ingest.StreamKeyHint == "" || resp.Ingest.RecordingPolicy != models.RecordingPolicyManual {
+		t.Fatalf("unexpected ingest summary %+v", resp.Ingest)
+	}
+	if resp.Session == nil || len(resp.Session.Renditions) != 2 || resp.Session.StreamState != streamStateLive {
+		t.Fatalf("expected the live session, got %+v", resp.Session)
+	}
+	if resp.Recordings.Error != "" || len(resp.Recordings.Items) != dashboardRecordingCount {
+		t.Fatalf("expected the newest %d recordings, got %+v", dashboardRecordingCount, resp.Recordings)
+	}
+	for i, item := range resp.Recordings.Items {
+		if item.Status != "unpublished" {
+			t.Fatalf("expected unpublished recordings, got %+v", item)
+		}
+		if i > 0 && item.CreatedAt > resp.Recordings.Items[i-1].CreatedAt {
+			t.Fatalf("expected recordings newest first, got %+v", resp.Recordings.Items)
+		}
+	}
+	if resp.Followers.Total != 1 || resp.Followers.Gained != 1 {
+		t.Fatalf("unexpected followers %+v", resp.Followers)
+	}
+	if resp.Revenue == nil || len(resp.Revenue.Totals) != 1 {
+		t.Fatalf("expected revenue for the owner, got %+v", resp.Revenue)
+	}
+	total := resp.Revenue.Totals[0]
+	if total.Currency != "USD" || total.Tips != 2 || total.TipAmount.DecimalString() != "3.5" || total.Subscriptions != 1 || total.SubscriptionAmount.DecimalString() != "4.99" {
+		t.Fatalf("unexpected revenue totals %+v", total)
+	}
+	if resp.Reports.Open != 1 {
+		t.Fatalf("expected one open report, got %+v", resp.Reports)
+	}
+	if resp.Uploads.Error != "" || len(resp.Uploads.Pending) != 1 || resp.Uploads.Pending[0].Title != "Pending" {
+		t.Fatalf("expected the pending upload, got %+v", resp.Uploads)
+	}
+}
+
+func TestChannelDashboardFiltersByRole(t *testing.T) {
+	f := newDashboardFixture(t)
+
+	moderator := decodeDashboard(t, f.get(t, &f.moderator))
+	if moderator.Revenue != nil {
+		t.Fatalf("expected moderators not to see revenue, got %+v", moderator.Revenue)
+	}
+	if moderator.Ingest.StreamKeyHint != "" || moderator.Channel.StreamKeyHint != "" {
+		t.Fatalf("expected moderators not to see the stream key hint, got %+v", moderator.Ingest)
+	}
+	if moderator.Reports.Open != 1 || len(moderator.Recordings.Items) == 0 {
+		t.Fatalf("expected moderators to see the other sections, got %+v", moderator)
+	}
+	rec := f.get(t, &f.moderator)
+	if strings.Contains(rec.Body.String(), `"revenue"`) {
+		t.Fatalf("expected no revenue key for moderators, got %s", rec.Body.String())
+	}
+
+	admin := decodeDashboard(t, f.get(t, &f.admin))
+	if admin.Revenue == nil || admin.Ingest.StreamKeyHint == "" {
+		t.Fatalf("expected admins to see everything, got %+v", admin)
+	}
+
+	if rec := f.get(t, &f.viewer); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected viewers to be refused, got %d", rec.Code)
+	}
+	if rec := f.get(t, nil); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected anonymous requests to be refused, got %d", rec.Code)
+	}
+}
+
+// failingDashboardRepository fails the dashboard's recording and report
+// lookups.
+type failingDashboardRepository struct {
+	storage.Repository
+}
+
+func (failingDashboardRepository) ListRecordings(string, bool) ([]models.Recording, error) {
+	return nil, errors.New("recordings offline")
+}
+
+func (failingDashboardRepository) ListChatReports(string, bool) ([]models.ChatReport, error) {
+	return nil, errors.New("reports offline")
+}
+
+func TestChannelDashboardMarksFailedSections(t *testing.T) {
+	f := newDashboardFixture(t)
+	f.handler.Store = failingDashboardRepository{Repository: f.store}
+
+	rec := f.get(t, &f.owner)
+	resp := decodeDashboard(t, rec)
+	if resp.Recordings.Error != dashboardSectionUnavailable || resp.Reports.Error != dashboardSectionUnavailable {
+		t.Fatalf("expected failed sections to be marked, got recordings %+v reports %+v", resp.Recordings, resp.Reports)
+	}
+	if strings.Contains(rec.Body.String(), "offline") {
+		t.Fatalf("expected internal errors not to leak, got %s", rec.Body.String())
+	}
+	if resp.Followers.Error != "" || resp.Followers.Total != 1 || resp.Uploads.Error != "" || len(resp.Uploads.Pending) != 1 || resp.Revenue == nil || resp.Revenue.Error != "" {
+		t.Fatalf("expected the other sections to load, got %+v", resp)
+	}
+}
+
+func TestChannelDashboardConcurrentRequests(t *testing.T) {
+	f := newDashboardFixture(t)
+	var wg sync.WaitGroup
+	codes := make([]int, 8)
+	for i := range codes {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			user := f.owner
+			if i%2 == 1 {
+				user = f.moderator
+			}
+			req := withUser(httptest.NewRequest(http.MethodGet, "/api/channels/"+f.channel.ID+"/dashboard", nil), user)
+			rec := httptest.NewRecorder()
+			f.handler.ChannelByID(rec, req)
+			codes[i] = rec.Code
+		}(i)
+	}
+	wg.Wait()
+	for i, code := range codes {
+		if code != http.StatusOK {
+			t.Fatalf("request %d: expected 200, got %d", i, code)
+		}
+	}
+}
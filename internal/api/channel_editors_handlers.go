@@ -0,0 +1,163 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+// grantChannelEditorRequest grants editor access. Duration, a Go duration
+// such as "3h", or ExpiresAt, an RFC 3339 timestamp, limits the grant; with
+// neither it lasts until revoked.
+type grantChannelEditorRequest struct {
+	UserID    string `json:"userId"`
+	Duration  string `json:"duration,omitempty"`
+	ExpiresAt string `json:"expiresAt,omitempty"`
+}
+
+type channelEditorResponse struct {
+	ChannelID        string `json:"channelId"`
+	UserID           string `json:"userId"`
+	DisplayName      string `json:"displayName,omitempty"`
+	GrantedBy        string `json:"grantedBy,omitempty"`
+	GrantedAt        string `json:"grantedAt"`
+	ExpiresAt        string `json:"expiresAt,omitempty"`
+	RemainingSeconds *int64 `json:"remainingSeconds,omitempty"`
+}
+
+func (h *Handler) newChannelEditorResponse(editor models.ChannelEditor) channelEditorResponse {
+	response := channelEditorResponse{
+		ChannelID: editor.ChannelID,
+		UserID:    editor.UserID,
+		GrantedBy: editor.GrantedBy,
+		GrantedAt: editor.GrantedAt.Format(time.RFC3339Nano),
+	}
+	response.ExpiresAt, response.RemainingSeconds = h.grantExpiryFields(editor.ExpiresAt)
+	if user, ok := h.Store.GetUser(editor.UserID); ok {
+		response.DisplayName = user.DisplayName
+	}
+	return response
+}
+
+// parseGrantExpiry resolves the optional duration or expiresAt of a grant
+// request against now. Both empty means the grant does not expire. The
+// minimum lifetime is enforced by the store.
+func parseGrantExpiry(duration, expiresAt string, now time.Time) (*time.Time, error) {
+	duration = strings.TrimSpace(duration)
+	expiresAt = strings.TrimSpace(expiresAt)
+	switch {
+	case duration != "" && expiresAt != "":
+		return nil, ValidationError("set duration or expiresAt, not both")
+	case duration != "":
+		parsed, err := time.ParseDuration(duration)
+		if err != nil || parsed <= 0 {
+			return nil, ValidationError("duration must be a positive duration such as 90m or 3h")
+		}
+		expires := now.Add(parsed).UTC()
+		return &expires, nil
+	case expiresAt != "":
+		parsed, err := time.Parse(time.RFC3339, expiresAt)
+		if err != nil {
+			return nil, ValidationError("expiresAt must be an RFC 3339 timestamp")
+		}
+		expires := parsed.UTC()
+		return &expires, nil
+	}
+	return nil, nil
+}
+
+// grantExpiryFields formats a grant's expiry and the whole seconds left
+// before it, both omitted for grants that do not expire.
+func (h *Handler) grantExpiryFields(expiresAt *time.Time) (string, *int64) {
+	if expiresAt == nil {
+		return "", nil
+	}
+	remaining := int64(expiresAt.Sub(h.now()) / time.Second)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return expiresAt.UTC().Format(time.RFC3339Nano), &remaining
+}
+
+// writeGrantError reports a failed grant, flagging a too-short expiry as a
+// validation error.
+func writeGrantError(w http.ResponseWriter, err error) {
+	if errors.Is(err, storage.ErrGrantExpiryTooSoon) {
+		WriteRequestError(w, ValidationError(err.Error()))
+		return
+	}
+	writeStorageError(w, http.StatusBadRequest, err)
+}
+
+// handleChannelEditors serves /api/channels/{id}/editors and
+// /api/channels/{id}/editors/{userId}. Only users who can manage the channel
+// may grant or revoke editor access. Grants may be time-limited.
+func (h *Handler) handleChannelEditors(channel models.Channel, remaining []string, w http.ResponseWriter, r *http.Request) {
+	actor, ok := h.ensureChannelAccess(w, r, channel)
+	if !ok {
+		return
+	}
+	if len(remaining) > 1 {
+		WriteError(w, http.StatusNotFound, fmt.Errorf("unknown channel editors path"))
+		return
+	}
+
+	if len(remaining) == 1 && strings.TrimSpace(remaining[0]) != "" {
+		if r.Method != http.MethodDelete {
+			WriteMethodNotAllowed(w, r, http.MethodDelete)
+			return
+		}
+		if err := h.Store.RevokeChannelEditor(channel.ID, strings.TrimSpace(remaining[0])); err != nil {
+			writeStorageError(w, http.StatusBadRequest, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		editors, err := h.Store.ListChannelEditors(channel.ID)
+		if err != nil {
+			writeStorageError(w, http.StatusBadRequest, err)
+			return
+		}
+		response := make([]channelEditorResponse, 0, len(editors))
+		for _, editor := range editors {
+			response = append(response, h.newChannelEditorResponse(editor))
+		}
+		WriteJSON(w, http.StatusOK, response)
+	case http.MethodPost:
+		var req grantChannelEditorRequest
+		if !DecodeAndValidate(w, r, &req) {
+			return
+		}
+		userID := strings.TrimSpace(req.UserID)
+		if userID == "" {
+			WriteRequestError(w, ValidationError("userId is required"))
+			return
+		}
+		if _, exists := h.Store.GetUser(userID); !exists {
+			WriteRequestError(w, ValidationError(fmt.Sprintf("user %s not found", userID)))
+			return
+		}
+		expiresAt, err := parseGrantExpiry(req.Duration, req.ExpiresAt, h.now())
+		if err != nil {
+			WriteRequestError(w, err)
+			return
+		}
+		editor, err := h.Store.GrantChannelEditor(channel.ID, userID, actor.ID, expiresAt)
+		if err != nil {
+			writeGrantError(w, err)
+			return
+		}
+		WriteJSON(w, http.StatusCreated, h.newChannelEditorResponse(editor))
+	default:
+		WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodPost)
+	}
+}
@@ -0,0 +1,55 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+type channelFollowerResponse struct {
+	UserID      string `json:"userId"`
+	DisplayName string `json:"displayName"`
+	AvatarURL   string `json:"avatarUrl,omitempty"`
+	FollowedAt  string `json:"followedAt"`
+}
+
+// handleChannelFollowers serves GET /api/channels/{id}/followers, newest
+// first and paged by page and perPage. Follower lists are limited to the
+// channel owner and platform moderators so viewers cannot enumerate them.
+func (h *Handler) handleChannelFollowers(channel models.Channel, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	actor, ok := h.requireAuthenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	if !h.canModerateChannel(actor, channel) {
+		WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
+		return
+	}
+	page, perPage, err := parsePageParams(r.URL.Query())
+	if err != nil {
+		WriteRequestError(w, err)
+		return
+	}
+	followers, err := h.Store.ListChannelFollowers(channel.ID, storage.FollowerListOptions{Limit: perPage, Offset: (page - 1) * perPage})
+	if err != nil {
+		writeStorageError(w, http.StatusInternalServerError, err)
+		return
+	}
+	items := make([]channelFollowerResponse, 0, len(followers.Followers))
+	for _, follower := range followers.Followers {
+		items = append(items, channelFollowerResponse{
+			UserID:      follower.UserID,
+			DisplayName: follower.DisplayName,
+			AvatarURL:   follower.AvatarURL,
+			FollowedAt:  follower.FollowedAt.Format(time.RFC3339Nano),
+		})
+	}
+	WriteJSON(w, http.StatusOK, pageResponse{Items: items, Total: followers.Total, Page: page, PerPage: perPage})
+}
@@ -0,0 +1,136 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/chat"
+	"bitriver-live/internal/models"
+)
+
+// grantChannelModeratorRequest grants chat moderator access. Duration or
+// ExpiresAt limits the grant, as for editors; with neither it lasts until
+// revoked.
+type grantChannelModeratorRequest struct {
+	UserID    string `json:"userId"`
+	Duration  string `json:"duration,omitempty"`
+	ExpiresAt string `json:"expiresAt,omitempty"`
+}
+
+type channelModeratorResponse struct {
+	ChannelID        string `json:"channelId"`
+	UserID           string `json:"userId"`
+	DisplayName      string `json:"displayName,omitempty"`
+	GrantedBy        string `json:"grantedBy,omitempty"`
+	GrantedAt        string `json:"grantedAt"`
+	ExpiresAt        string `json:"expiresAt,omitempty"`
+	RemainingSeconds *int64 `json:"remainingSeconds,omitempty"`
+}
+
+func (h *Handler) newChannelModeratorResponse(moderator models.ChannelModerator) channelModeratorResponse {
+	response := channelModeratorResponse{
+		ChannelID: moderator.ChannelID,
+		UserID:    moderator.UserID,
+		GrantedBy: moderator.GrantedBy,
+		GrantedAt: moderator.GrantedAt.Format(time.RFC3339Nano),
+	}
+	response.ExpiresAt, response.RemainingSeconds = h.grantExpiryFields(moderator.ExpiresAt)
+	if user, ok := h.Store.GetUser(moderator.UserID); ok {
+		response.DisplayName = user.DisplayName
+	}
+	return response
+}
+
+// handleChannelModerators serves /api/channels/{id}/moderators and
+// /api/channels/{id}/moderators/{userId}. Only users who can manage the
+// channel may grant or revoke moderator access. Changes are announced to the
+// channel's chat so clients can update the user's badge.
+func (h *Handler) handleChannelModerators(channel models.Channel, remaining []string, w http.ResponseWriter, r *http.Request) {
+	actor, ok := h.ensureChannelAccess(w, r, channel)
+	if !ok {
+		return
+	}
+	if len(remaining) > 1 {
+		WriteError(w, http.StatusNotFound, fmt.Errorf("unknown channel moderators path"))
+		return
+	}
+
+	if len(remaining) == 1 && strings.TrimSpace(remaining[0]) != "" {
+		if r.Method != http.MethodDelete {
+			WriteMethodNotAllowed(w, r, http.MethodDelete)
+			return
+		}
+		userID := strings.TrimSpace(remaining[0])
+		wasModerator := h.Store.IsChannelModerator(channel.ID, userID)
+		if err := h.Store.RevokeChannelModerator(channel.ID, userID); err != nil {
+			writeStorageError(w, http.StatusBadRequest, err)
+			return
+		}
+		h.auditLogger().Info("audit", "action", "channel.moderator_revoke", "user_id", actor.ID, "channel_id", channel.ID, "target_user_id", userID)
+		if wasModerator {
+			h.announceModerator(r, chat.ModeratorEvent{Action: chat.ModeratorActionRemoved, ChannelID: channel.ID, UserID: userID})
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		moderators, err := h.Store.ListChannelModerators(channel.ID)
+		if err != nil {
+			writeStorageError(w, http.StatusBadRequest, err)
+			return
+		}
+		response := make([]channelModeratorResponse, 0, len(moderators))
+		for _, moderator := range moderators {
+			response = append(response, h.newChannelModeratorResponse(moderator))
+		}
+		WriteJSON(w, http.StatusOK, response)
+	case http.MethodPost:
+		var req grantChannelModeratorRequest
+		if !DecodeAndValidate(w, r, &req) {
+			return
+		}
+		userID := strings.TrimSpace(req.UserID)
+		if userID == "" {
+			WriteRequestError(w, ValidationError("userId is required"))
+			return
+		}
+		if _, exists := h.Store.GetUser(userID); !exists {
+			WriteRequestError(w, ValidationError(fmt.Sprintf("user %s not found", userID)))
+			return
+		}
+		expiresAt, err := parseGrantExpiry(req.Duration, req.ExpiresAt, h.now())
+		if err != nil {
+			WriteRequestError(w, err)
+			return
+		}
+		moderator, err := h.Store.GrantChannelModerator(channel.ID, userID, actor.ID, expiresAt)
+		if err != nil {
+			writeGrantError(w, err)
+			return
+		}
+		expires := ""
+		if moderator.ExpiresAt != nil {
+			expires = moderator.ExpiresAt.Format(time.RFC3339)
+		}
+		h.auditLogger().Info("audit", "action", "channel.moderator_grant", "user_id", actor.ID, "channel_id", channel.ID, "target_user_id", userID, "expires_at", expires)
+		h.announceModerator(r, chat.ModeratorEvent{Action: chat.ModeratorActionAdded, ChannelID: channel.ID, UserID: userID, ExpiresAt: moderator.ExpiresAt})
+		WriteJSON(w, http.StatusCreated, h.newChannelModeratorResponse(moderator))
+	default:
+		WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodPost)
+	}
+}
+
+// announceModerator tells the channel's chat about a moderator change. The
+// grant is already stored, so a failed announcement is only logged.
+func (h *Handler) announceModerator(r *http.Request, event chat.ModeratorEvent) {
+	if h.ChatGateway == nil {
+		return
+	}
+	if err := h.ChatGateway.AnnounceModerator(r.Context(), event); err != nil {
+		h.logger().Warn("announce moderator change", "channel_id", event.ChannelID, "user_id", event.UserID, "error", err)
+	}
+}
@@ -0,0 +1,146 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/auth"
+	"bitriver-live/internal/chat"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+func TestChannelModeratorGrantsExpire(t *testing.T) {
+	now := time.Date(2026, 3, 1, 20, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	store, err := storage.NewStorage(filepath.Join(t.TempDir(), "store.json"), storage.WithGrantClock(clock))
+	if err != nil {
+		t.Fatalf("NewStorage: %v", err)
+	}
+	handler := NewHandler(store, auth.NewSessionManager(24*time.Hour))
+	handler.Now = clock
+	queue := chat.NewMemoryQueue(4)
+	events := queue.Subscribe()
+	defer events.Close()
+	handler.ChatGateway = chat.NewGateway(chat.GatewayConfig{Queue: queue, Store: store})
+	nextModerator := func() chat.ModeratorEvent {
+		t.Helper()
+		select {
+		case evt := <-events.Events():
+			if evt.Type != chat.EventTypeModerator || evt.Moderator == nil {
+				t.Fatalf("expected a moderator event, got %+v", evt)
+			}
+			return *evt.Moderator
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for moderator event")
+		}
+		return chat.ModeratorEvent{}
+	}
+
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser owner: %v", err)
+	}
+	guest, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Guest", Email: "guest@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser guest: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Arena", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+
+	serve := func(method, path string, user models.User, body string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(method, path, strings.NewReader(body)), user)
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		return rec
+	}
+	moderatorsPath := "/api/channels/" + channel.ID + "/moderators"
+	list := func() []channelModeratorResponse {
+		t.Helper()
+		rec := serve(http.MethodGet, moderatorsPath, owner, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200 listing moderators, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var moderators []channelModeratorResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &moderators); err != nil {
+			t.Fatalf("decode moderators: %v", err)
+		}
+		return moderators
+	}
+	pin := func() int {
+		return serve(http.MethodPost, "/api/channels/"+channel.ID+"/chat/pin", guest, `{"content":"Guest host tonight"}`).Code
+	}
+
+	for _, body := range []string{
+		`{"userId":"` + guest.ID + `","duration":"4m"}`,
+		`{"userId":"` + guest.ID + `","expiresAt":"` + now.Add(time.Minute).Format(time.RFC3339) + `"}`,
+		`{"userId":"` + guest.ID + `","duration":"1h","expiresAt":"` + now.Add(time.Hour).Format(time.RFC3339) + `"}`,
+		`{"userId":"` + guest.ID + `","duration":"soon"}`,
+	} {
+		rec := serve(http.MethodPost, moderatorsPath, owner, body)
+		if rec.Code != http.StatusBadRequest || decodeAPIError(t, rec.Body.Bytes()).Error.Code != "validation_failed" {
+			t.Fatalf("expected 400 validation_failed for %s, got %d: %s", body, rec.Code, rec.Body.String())
+		}
+	}
+	if code := pin(); code != http.StatusForbidden {
+		t.Fatalf("expected 403 pinning before the grant, got %d", code)
+	}
+
+	rec := serve(http.MethodPost, moderatorsPath, owner, `{"userId":"`+guest.ID+`","duration":"2h"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201 granting a moderator, got %d: %s", rec.Code, rec.Body.String())
+	}
+	expiresAt := now.Add(2 * time.Hour)
+	if evt := nextModerator(); evt.Action != chat.ModeratorActionAdded || evt.UserID != guest.ID || evt.ExpiresAt == nil || !evt.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("unexpected grant event: %+v", evt)
+	}
+	if code := pin(); code != http.StatusOK {
+		t.Fatalf("expected 200 pinning as a channel moderator, got %d", code)
+	}
+	if evt := <-events.Events(); evt.Type != chat.EventTypePin {
+		t.Fatalf("expected the pin to be announced, got %+v", evt)
+	}
+
+	now = now.Add(30 * time.Minute)
+	moderators := list()
+	if len(moderators) != 1 || moderators[0].UserID != guest.ID || moderators[0].DisplayName != "Guest" || moderators[0].GrantedBy != owner.ID {
+		t.Fatalf("unexpected moderators listing: %+v", moderators)
+	}
+	if moderators[0].ExpiresAt != expiresAt.Format(time.RFC3339Nano) || moderators[0].RemainingSeconds == nil || *moderators[0].RemainingSeconds != 90*60 {
+		t.Fatalf("expected 90 minutes remaining until %s, got %+v", expiresAt, moderators[0])
+	}
+
+	now = expiresAt
+	if code := pin(); code != http.StatusForbidden {
+		t.Fatalf("expected 403 pinning the moment the grant expires, got %d", code)
+	}
+	if moderators := list(); len(moderators) != 0 {
+		t.Fatalf("expected the expired grant to be hidden, got %+v", moderators)
+	}
+
+	rec = serve(http.MethodPost, moderatorsPath, owner, `{"userId":"`+guest.ID+`"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201 granting a permanent moderator, got %d: %s", rec.Code, rec.Body.String())
+	}
+	nextModerator()
+	if moderators := list(); len(moderators) != 1 || moderators[0].ExpiresAt != "" || moderators[0].RemainingSeconds != nil {
+		t.Fatalf("expected a grant without expiry, got %+v", moderators)
+	}
+	rec = serve(http.MethodDelete, moderatorsPath+"/"+guest.ID, owner, "")
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 revoking a moderator, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if evt := nextModerator(); evt.Action != chat.ModeratorActionRemoved || evt.UserID != guest.ID {
+		t.Fatalf("unexpected revoke event: %+v", evt)
+	}
+	if code := pin(); code != http.StatusForbidden {
+		t.Fatalf("expected 403 pinning after the grant is revoked, got %d", code)
+	}
+}
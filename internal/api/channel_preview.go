@@ -0,0 +1,121 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"bitriver-live/internal/ingest"
+	"bitriver-live/internal/models"
+)
+
+// channelPreviewTTL is how long a captured preview frame is reused for a
+// channel. The transcoder refreshes its frame on the same cadence, so
+// directory cards stay current without every viewer reaching the transcoder.
+const channelPreviewTTL = 30 * time.Second
+
+// livePreviewThumbnailURL returns the session's mirrored preview frame with a
+// version parameter that advances every channelPreviewTTL, so clients and
+// CDNs fetch each new capture instead of a cached one. It returns "" for
+// sessions without a preview and during the first interval, before the
+// transcoder has written a frame.
+func livePreviewThumbnailURL(session models.StreamSession, now time.Time) string {
+	previewURL := strings.TrimSpace(session.PreviewURL)
+	if previewURL == "" || session.EndedAt != nil {
+		return ""
+	}
+	version := int64(now.Sub(session.StartedAt) / channelPreviewTTL)
+	if version < 1 {
+		return ""
+	}
+	separator := "?"
+	if strings.Contains(previewURL, "?") {
+		separator = "&"
+	}
+	return fmt.Sprintf("%s%sv=%d", previewURL, separator, version)
+}
+
+type channelPreview struct {
+	frame      ingest.Frame
+	capturedAt time.Time
+}
+
+// channelPreviewCache holds the latest preview frame per channel. The zero
+// value is ready to use.
+type channelPreviewCache struct {
+	mu      sync.Mutex
+	entries map[string]channelPreview
+}
+
+func (c *channelPreviewCache) get(channelID string, now time.Time) (channelPreview, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[channelID]
+	if !ok || now.Sub(entry.capturedAt) >= channelPreviewTTL {
+		return channelPreview{}, false
+	}
+	return entry, true
+}
+
+func (c *channelPreviewCache) put(channelID string, entry channelPreview) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]channelPreview)
+	}
+	for id, existing := range c.entries {
+		if entry.capturedAt.Sub(existing.capturedAt) >= channelPreviewTTL {
+			delete(c.entries, id)
+		}
+	}
+	c.entries[channelID] = entry
+}
+
+// handleChannelPreview serves a still frame of a live channel for directory
+// cards. Offline channels and streams without a frame yet get a 404 so
+// clients fall back to the channel artwork.
+func (h *Handler) handleChannelPreview(channel models.Channel, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	if channel.LiveState != "live" {
+		w.Header().Set("Cache-Control", "no-store")
+		WriteError(w, http.StatusNotFound, fmt.Errorf("channel %s is not live", channel.ID))
+		return
+	}
+
+	now := h.now()
+	entry, ok := h.previews.get(channel.ID, now)
+	if !ok {
+		frame, err := h.Store.ChannelPreview(channel.ID)
+		if err != nil {
+			w.Header().Set("Cache-Control", "no-store")
+			if errors.Is(err, ingest.ErrFrameUnavailable) {
+				WriteError(w, http.StatusNotFound, fmt.Errorf("preview unavailable for channel %s", channel.ID))
+				return
+			}
+			WriteError(w, http.StatusBadGateway, fmt.Errorf("capture preview: %w", err))
+			return
+		}
+		entry = channelPreview{frame: frame, capturedAt: now}
+		h.previews.put(channel.ID, entry)
+	}
+
+	maxAge := int((channelPreviewTTL - now.Sub(entry.capturedAt)).Seconds())
+	if maxAge < 0 {
+		maxAge = 0
+	}
+	contentType := entry.frame.ContentType
+	if contentType == "" {
+		contentType = "image/jpeg"
+	}
+	w.Header().Set("Content-Type", contentType)
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", maxAge))
+	w.Header().Set("Last-Modified", entry.capturedAt.UTC().Format(http.TimeFormat))
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(entry.frame.Data)
+}
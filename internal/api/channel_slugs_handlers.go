@@ -0,0 +1,84 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+
+	"bitriver-live/internal/storage"
+)
+
+// channelSlugRedirectResponse answers a lookup by a slug the channel has
+// since replaced. Location repeats the redirect target for clients that do
+// not follow redirects.
+type channelSlugRedirectResponse struct {
+	ChannelID string `json:"channelId"`
+	Slug      string `json:"slug"`
+	Location  string `json:"location"`
+}
+
+// writeChannelSlugError answers with the status matching a slug rule that
+// err breaks. It reports false, writing nothing, for any other error.
+func writeChannelSlugError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, storage.ErrChannelSlugInvalid), errors.Is(err, storage.ErrChannelSlugReserved):
+		WriteRequestError(w, RequestError{Status: http.StatusBadRequest, CodeVal: "invalid_slug", Message: err.Error(), Err: err})
+	case errors.Is(err, storage.ErrChannelSlugTaken):
+		WriteRequestError(w, RequestError{Status: http.StatusConflict, CodeVal: "slug_taken", Message: err.Error(), Err: err})
+	case errors.Is(err, storage.ErrChannelSlugCooldown):
+		WriteRequestError(w, RequestError{Status: http.StatusConflict, CodeVal: "slug_cooldown", Message: err.Error(), Err: err})
+	default:
+		return false
+	}
+	return true
+}
+
+// writeChannel serves a channel: the full view to its owner and channel
+// managers, the cached public view to everyone else.
+func (h *Handler) writeChannel(w http.ResponseWriter, r *http.Request, channelID string) {
+	if actor, ok := UserFromContext(r.Context()); ok {
+		channel, exists := h.Store.GetChannel(channelID)
+		if !exists {
+			WriteError(w, http.StatusNotFound, fmt.Errorf("channel %s not found", channelID))
+			return
+		}
+		if canViewChannelDetails(actor, channel) {
+			h.writeETagJSON(w, r, viewerETag("channel"), newChannelResponse(channel))
+			return
+		}
+	}
+	h.writeCachedJSON(w, r, sharedETag("channel"), channelCacheNamespace, channelID, func() (interface{}, error) {
+		channel, exists := h.Store.GetChannel(channelID)
+		if !exists {
+			return nil, RequestError{Status: http.StatusNotFound, Message: fmt.Sprintf("channel %s not found", channelID)}
+		}
+		return newChannelPublicResponse(channel), nil
+	})
+}
+
+// channelBySlug serves /api/channels/by-slug/{slug}. A slug the channel used
+// before answers 301 Moved Permanently pointing at its current slug, so old
+// links keep working and clients can update the URL they show.
+func (h *Handler) channelBySlug(w http.ResponseWriter, r *http.Request, slug string) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	match, ok := h.Store.FindChannelBySlug(slug)
+	if !ok {
+		WriteError(w, http.StatusNotFound, fmt.Errorf("channel %s not found", slug))
+		return
+	}
+	if match.Redirected {
+		location := "/api/channels/by-slug/" + url.PathEscape(match.Channel.Slug)
+		w.Header().Set("Location", location)
+		WriteJSON(w, http.StatusMovedPermanently, channelSlugRedirectResponse{
+			ChannelID: match.Channel.ID,
+			Slug:      match.Channel.Slug,
+			Location:  location,
+		})
+		return
+	}
+	h.writeChannel(w, r, match.Channel.ID)
+}
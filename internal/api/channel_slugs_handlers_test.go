@@ -0,0 +1,118 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+func TestChannelSlugChangeAndLookup(t *testing.T) {
+	handler, store := newTestHandler(t)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	handler.Now = func() time.Time { return now }
+
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Night Owls", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	if _, err := store.CreateChannel(viewer.ID, "Early Birds", "gaming", nil); err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+
+	get := func(path string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		return rec
+	}
+	rec := get("/api/channels/by-slug/Night-Owls")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected the derived slug to resolve, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var found channelPublicResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &found); err != nil {
+		t.Fatalf("decode channel: %v", err)
+	}
+	if found.ID != channel.ID || found.Slug != "night-owls" {
+		t.Fatalf("expected channel %s with slug night-owls, got %+v", channel.ID, found)
+	}
+
+	patch := func(user models.User, body string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(http.MethodPatch, "/api/channels/"+channel.ID, strings.NewReader(body)), user)
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		return rec
+	}
+	for _, tc := range []struct {
+		slug   string
+		status int
+		code   string
+	}{
+		{slug: "admin", status: http.StatusBadRequest, code: "invalid_slug"},
+		{slug: "no spaces", status: http.StatusBadRequest, code: "invalid_slug"},
+		{slug: "early-birds", status: http.StatusConflict, code: "slug_taken"},
+	} {
+		rec := patch(owner, `{"slug":"`+tc.slug+`"}`)
+		if rec.Code != tc.status || decodeAPIError(t, rec.Body.Bytes()).Error.Code != tc.code {
+			t.Fatalf("slug %q: expected %d %s, got %d: %s", tc.slug, tc.status, tc.code, rec.Code, rec.Body.String())
+		}
+	}
+	if rec := patch(viewer, `{"slug":"stolen-owls"}`); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected another user's change to be forbidden, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = patch(owner, `{"slug":"Owl-House","title":"The Owl House"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected the slug to change, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var updated channelResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &updated); err != nil {
+		t.Fatalf("decode channel: %v", err)
+	}
+	if updated.Slug != "owl-house" || updated.Title != "The Owl House" || updated.SlugChangedAt == nil {
+		t.Fatalf("expected the new slug, title, and change time, got %+v", updated)
+	}
+
+	rec = get("/api/channels/by-slug/night-owls")
+	if rec.Code != http.StatusMovedPermanently {
+		t.Fatalf("expected the old slug to redirect, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if location := rec.Header().Get("Location"); location != "/api/channels/by-slug/owl-house" {
+		t.Fatalf("expected a redirect to the new slug, got %q", location)
+	}
+	var redirect channelSlugRedirectResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &redirect); err != nil {
+		t.Fatalf("decode redirect: %v", err)
+	}
+	if redirect.ChannelID != channel.ID || redirect.Slug != "owl-house" {
+		t.Fatalf("unexpected redirect body %+v", redirect)
+	}
+	if rec := get("/api/channels/by-slug/owl-house"); rec.Code != http.StatusOK {
+		t.Fatalf("expected the new slug to resolve, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := get("/api/channels/by-slug/missing-owls"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected an unknown slug to 404, got %d", rec.Code)
+	}
+
+	rec = patch(owner, `{"slug":"owl-manor"}`)
+	if rec.Code != http.StatusConflict || decodeAPIError(t, rec.Body.Bytes()).Error.Code != "slug_cooldown" {
+		t.Fatalf("expected 409 slug_cooldown, got %d: %s", rec.Code, rec.Body.String())
+	}
+	now = now.Add(storage.ChannelSlugChangeCooldown)
+	if rec := patch(owner, `{"slug":"owl-manor"}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected a change after the cooldown, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,166 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+// channelStatusTTL bounds how long a channel's status is served from memory.
+// Starting and stopping a stream drop the entry on the instance that handled
+// it; the TTL bounds how stale other API instances can be.
+const channelStatusTTL = 5 * time.Second
+
+// maxChannelStatusBatch caps the IDs one batch status request may ask for.
+const maxChannelStatusBatch = 50
+
+// channelStatusResponse is the minimal live status bots and widgets poll.
+type channelStatusResponse struct {
+	ChannelID   string  `json:"channelId"`
+	Title       string  `json:"title"`
+	Live        bool    `json:"live"`
+	StreamState string  `json:"streamState"`
+	SessionID   string  `json:"sessionId,omitempty"`
+	StartedAt   *string `json:"startedAt,omitempty"`
+	ViewerCount int     `json:"viewerCount"`
+}
+
+// channelStatusBatchResponse maps each requested ID to its status, or to
+// null when no such channel exists.
+type channelStatusBatchResponse struct {
+	Channels map[string]*channelStatusResponse `json:"channels"`
+}
+
+type channelStatusEntry struct {
+	status   channelStatusResponse
+	exists   bool
+	cachedAt time.Time
+}
+
+// channelStatusCache holds the stored part of each channel's status, including
+// channels that were looked up and not found. Viewer counts are read from the
+// SRS tracker on every request instead. The zero value is ready to use.
+type channelStatusCache struct {
+	mu      sync.Mutex
+	entries map[string]channelStatusEntry
+}
+
+func (c *channelStatusCache) get(channelID string, now time.Time) (channelStatusEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.entries[channelID]
+	if !ok || now.Sub(entry.cachedAt) >= channelStatusTTL {
+		return channelStatusEntry{}, false
+	}
+	return entry, true
+}
+
+func (c *channelStatusCache) put(channelID string, entry channelStatusEntry) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.entries == nil {
+		c.entries = make(map[string]channelStatusEntry)
+	}
+	for id, existing := range c.entries {
+		if entry.cachedAt.Sub(existing.cachedAt) >= channelStatusTTL {
+			delete(c.entries, id)
+		}
+	}
+	c.entries[channelID] = entry
+}
+
+func (c *channelStatusCache) invalidate(channelID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.entries, channelID)
+}
+
+// channelStatus resolves a channel's live status, loading it from the store
+// when the cached entry is missing or expired.
+func (h *Handler) channelStatus(channelID string) (channelStatusResponse, bool) {
+	now := h.now()
+	entry, ok := h.statuses.get(channelID, now)
+	if !ok {
+		entry = channelStatusEntry{cachedAt: now}
+		if channel, exists := h.Store.GetChannel(channelID); exists {
+			entry.exists = true
+			entry.status = channelStatusResponse{ChannelID: channel.ID, Title: channel.Title, StreamState: streamStateOffline}
+			if channel.LiveState == "live" || channel.LiveState == "starting" {
+				session, live := h.Store.CurrentStreamSession(channel.ID)
+				entry.status.StreamState = h.streamState(channel, session, live)
+				if channel.LiveState == "live" {
+					entry.status.Live = true
+					if live {
+						entry.status.SessionID = session.ID
+						started := session.StartedAt.UTC().Format(time.RFC3339Nano)
+						entry.status.StartedAt = &started
+					}
+				}
+			}
+		}
+		h.statuses.put(channelID, entry)
+	}
+	if !entry.exists {
+		return channelStatusResponse{}, false
+	}
+	status := entry.status
+	if status.Live {
+		status.ViewerCount = h.srsTracker().current(channelID)
+	}
+	return status, true
+}
+
+// handleChannelStatus serves GET /api/channels/{id}/status.
+func (h *Handler) handleChannelStatus(channelID string, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	status, ok := h.channelStatus(channelID)
+	if !ok {
+		WriteError(w, http.StatusNotFound, fmt.Errorf("channel %s not found", channelID))
+		return
+	}
+	h.writeETagJSON(w, r, sharedETag("channel_status"), status)
+}
+
+// handleChannelStatuses serves GET /api/channels/status?ids=a,b,c. Unknown
+// IDs map to null so one stale ID does not fail the whole batch.
+func (h *Handler) handleChannelStatuses(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	ids := make([]string, 0)
+	seen := make(map[string]struct{})
+	for _, id := range strings.Split(r.URL.Query().Get("ids"), ",") {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		if _, dup := seen[id]; dup {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+	if len(ids) == 0 {
+		WriteRequestError(w, ValidationError("ids is required"))
+		return
+	}
+	if len(ids) > maxChannelStatusBatch {
+		WriteRequestError(w, ValidationError(fmt.Sprintf("at most %d channel ids may be requested at once", maxChannelStatusBatch)))
+		return
+	}
+	resp := channelStatusBatchResponse{Channels: make(map[string]*channelStatusResponse, len(ids))}
+	for _, id := range ids {
+		if status, ok := h.channelStatus(id); ok {
+			resp.Channels[id] = &status
+			continue
+		}
+		resp.Channels[id] = nil
+	}
+	h.writeETagJSON(w, r, sharedETag("channel_status"), resp)
+}
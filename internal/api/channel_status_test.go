@@ -0,0 +1,170 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+func getChannelStatus(t *testing.T, h *Handler, path, etag string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	if etag != "" {
+		req.Header.Set("If-None-Match", etag)
+	}
+	rec := httptest.NewRecorder()
+	h.ChannelByID(rec, req)
+	return rec
+}
+
+func decodeChannelStatus(t *testing.T, rec *httptest.ResponseRecorder) channelStatusResponse {
+	t.Helper()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var status channelStatusResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("decode status: %v", err)
+	}
+	return status
+}
+
+func newStatusChannel(t *testing.T, store *storage.Storage) (models.User, models.Channel) {
+	t.Helper()
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Creator", Email: "creator@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Live Show", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	return owner, channel
+}
+
+func TestChannelStatusFollowsStreamStartAndStop(t *testing.T) {
+	h, store := newTestHandler(t)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	h.Now = func() time.Time { return now }
+	owner, channel := newStatusChannel(t, store)
+	path := "/api/channels/" + channel.ID + "/status"
+
+	status := decodeChannelStatus(t, getChannelStatus(t, h, path, ""))
+	if status.Live || status.Title != "Live Show" || status.SessionID != "" || status.StartedAt != nil {
+		t.Fatalf("expected an offline status, got %+v", status)
+	}
+
+	streamAction := func(action string) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/stream/"+action, strings.NewReader(`{}`))
+		rec := httptest.NewRecorder()
+		h.ChannelByID(rec, withUser(req, owner))
+		if rec.Code != http.StatusCreated && rec.Code != http.StatusOK {
+			t.Fatalf("stream %s: unexpected status %d: %s", action, rec.Code, rec.Body.String())
+		}
+	}
+
+	streamAction("start")
+	status = decodeChannelStatus(t, getChannelStatus(t, h, path, ""))
+	session, ok := store.CurrentStreamSession(channel.ID)
+	if !ok || !status.Live || status.SessionID != session.ID || status.StartedAt == nil {
+		t.Fatalf("expected starting the stream to refresh the status, got %+v", status)
+	}
+
+	h.srsTracker().increment(channel.ID)
+	h.srsTracker().increment(channel.ID)
+	if status = decodeChannelStatus(t, getChannelStatus(t, h, path, "")); status.ViewerCount != 2 {
+		t.Fatalf("expected the current viewer count, got %+v", status)
+	}
+
+	streamAction("stop")
+	status = decodeChannelStatus(t, getChannelStatus(t, h, path, ""))
+	if status.Live || status.SessionID != "" || status.ViewerCount != 0 {
+		t.Fatalf("expected stopping the stream to refresh the status, got %+v", status)
+	}
+
+	// Changes made elsewhere show up once the cached entry expires.
+	if _, err := store.StartStream(channel.ID, []string{"720p"}); err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	if status = decodeChannelStatus(t, getChannelStatus(t, h, path, "")); status.Live {
+		t.Fatalf("expected the cached status within its TTL, got %+v", status)
+	}
+	now = now.Add(channelStatusTTL)
+	if status = decodeChannelStatus(t, getChannelStatus(t, h, path, "")); !status.Live {
+		t.Fatalf("expected the status reloaded after its TTL, got %+v", status)
+	}
+
+	if rec := getChannelStatus(t, h, "/api/channels/missing/status", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown channel, got %d", rec.Code)
+	}
+}
+
+func TestChannelStatusBatchReturnsPartialResults(t *testing.T) {
+	h, store := newTestHandler(t)
+	_, channel := newStatusChannel(t, store)
+
+	rec := getChannelStatus(t, h, "/api/channels/status?ids="+channel.ID+",missing,"+channel.ID, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp channelStatusBatchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode batch: %v", err)
+	}
+	if len(resp.Channels) != 2 {
+		t.Fatalf("expected two entries, got %+v", resp.Channels)
+	}
+	if known := resp.Channels[channel.ID]; known == nil || known.ChannelID != channel.ID || known.Live {
+		t.Fatalf("expected the known channel's status, got %+v", known)
+	}
+	if missing, present := resp.Channels["missing"]; !present || missing != nil {
+		t.Fatalf("expected null for an unknown channel, got %+v (present %v)", missing, present)
+	}
+
+	ids := make([]string, maxChannelStatusBatch+1)
+	for i := range ids {
+		ids[i] = fmt.Sprintf("channel-%d", i)
+	}
+	for _, query := range []string{"", "?ids=", "?ids=" + strings.Join(ids, ",")} {
+		if rec := getChannelStatus(t, h, "/api/channels/status"+query, ""); rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %q, got %d", query, rec.Code)
+		}
+	}
+}
+
+func TestChannelStatusNotModified(t *testing.T) {
+	h, store := newTestHandler(t)
+	_, channel := newStatusChannel(t, store)
+	if _, err := store.StartStream(channel.ID, []string{"720p"}); err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	path := "/api/channels/" + channel.ID + "/status"
+
+	first := getChannelStatus(t, h, path, "")
+	etag := first.Header().Get("ETag")
+	if first.Code != http.StatusOK || etag == "" || first.Header().Get("Cache-Control") != "public, max-age=5" {
+		t.Fatalf("expected a cacheable response with an ETag, got %d %v", first.Code, first.Header())
+	}
+	if rec := getChannelStatus(t, h, path, etag); rec.Code != http.StatusNotModified || rec.Body.Len() != 0 {
+		t.Fatalf("expected 304 for a matching ETag, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	h.srsTracker().increment(channel.ID)
+	rec := getChannelStatus(t, h, path, etag)
+	if rec.Code != http.StatusOK || rec.Header().Get("ETag") == etag {
+		t.Fatalf("expected a new viewer to change the ETag, got %d", rec.Code)
+	}
+
+	batch := getChannelStatus(t, h, "/api/channels/status?ids="+channel.ID, "")
+	if rec := getChannelStatus(t, h, "/api/channels/status?ids="+channel.ID, batch.Header().Get("ETag")); rec.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 for an unchanged batch, got %d", rec.Code)
+	}
+}
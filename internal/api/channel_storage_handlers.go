@@ -0,0 +1,114 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"bitriver-live/internal/authz"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+// channelStorageQuotaRequest sets a channel's storage quota override. A null
+// quotaBytes returns the channel to the platform default; zero lifts the
+// quota.
+type channelStorageQuotaRequest struct {
+	QuotaBytes *int64 `json:"quotaBytes"`
+}
+
+type channelStorageItemResponse struct {
+	Kind      string `json:"kind"`
+	ID        string `json:"id"`
+	Title     string `json:"title"`
+	SizeBytes int64  `json:"sizeBytes"`
+	OverQuota bool   `json:"overQuota,omitempty"`
+	CreatedAt string `json:"createdAt"`
+}
+
+// channelStorageResponse reports a channel's storage. QuotaBytes is the
+// quota in force, zero when unlimited; QuotaOverride is set when an admin
+// overrode the platform default.
+type channelStorageResponse struct {
+	ChannelID     string                       `json:"channelId"`
+	UsedBytes     int64                        `json:"usedBytes"`
+	QuotaBytes    int64                        `json:"quotaBytes"`
+	QuotaOverride *int64                       `json:"quotaOverride,omitempty"`
+	Items         []channelStorageItemResponse `json:"items"`
+}
+
+func newChannelStorageResponse(report storage.ChannelStorageReport) channelStorageResponse {
+	response := channelStorageResponse{
+		ChannelID:     report.ChannelID,
+		UsedBytes:     report.UsedBytes,
+		QuotaBytes:    report.QuotaBytes,
+		QuotaOverride: report.QuotaOverride,
+		Items:         make([]channelStorageItemResponse, 0, len(report.Items)),
+	}
+	for _, item := range report.Items {
+		response.Items = append(response.Items, channelStorageItemResponse{
+			Kind:      item.Kind,
+			ID:        item.ID,
+			Title:     item.Title,
+			SizeBytes: item.SizeBytes,
+			OverQuota: item.OverQuota,
+			CreatedAt: item.CreatedAt.Format(time.RFC3339Nano),
+		})
+	}
+	return response
+}
+
+// storageQuotaError maps ErrStorageQuotaExceeded to a conflict clients can
+// recognise by its code, and returns false for any other error.
+func storageQuotaError(err error) (RequestError, bool) {
+	if !errors.Is(err, storage.ErrStorageQuotaExceeded) {
+		return RequestError{}, false
+	}
+	return RequestError{Status: http.StatusConflict, CodeVal: "storage_quota_exceeded", Message: "the channel has used its storage quota; delete recordings or uploads to free space", Err: err}, true
+}
+
+// handleChannelStorage serves /api/channels/{id}/storage. Channel managers
+// read the channel's usage, quota, and the items that take up space,
+// largest first. Platform administrators change the quota (PATCH).
+func (h *Handler) handleChannelStorage(channel models.Channel, remaining []string, w http.ResponseWriter, r *http.Request) {
+	if len(remaining) > 0 && remaining[0] != "" {
+		WriteError(w, http.StatusNotFound, fmt.Errorf("unknown storage path"))
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		if _, ok := h.ensureChannelAccess(w, r, channel); !ok {
+			return
+		}
+	case http.MethodPatch:
+		user, ok := h.requireAuthenticatedUser(w, r)
+		if !ok {
+			return
+		}
+		if !authz.Has(user, authz.PlatformManage) {
+			WriteError(w, http.StatusForbidden, fmt.Errorf("only platform administrators can change storage quotas"))
+			return
+		}
+		if !h.requireScope(w, r, storage.APITokenScopeManageChannel) {
+			return
+		}
+		var req channelStorageQuotaRequest
+		if !DecodeAndValidate(w, r, &req) {
+			return
+		}
+		if _, err := h.Store.SetChannelStorageQuota(channel.ID, req.QuotaBytes); err != nil {
+			writeStorageError(w, http.StatusBadRequest, err)
+			return
+		}
+	default:
+		WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodPatch)
+		return
+	}
+	report, err := h.Store.ChannelStorageUsage(channel.ID)
+	if err != nil {
+		writeStorageError(w, http.StatusInternalServerError, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, newChannelStorageResponse(report))
+}
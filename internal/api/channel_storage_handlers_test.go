@@ -0,0 +1,105 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+func TestChannelStorageReportsUsageAndEnforcesQuota(t *testing.T) {
+	handler, store := newTestHandler(t)
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser owner: %v", err)
+	}
+	admin, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Admin", Email: "admin@example.com", Roles: []string{"admin"}})
+	if err != nil {
+		t.Fatalf("CreateUser admin: %v", err)
+	}
+	viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser viewer: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Archive", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+
+	storageRequest := func(method string, user models.User, body string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(method, "/api/channels/"+channel.ID+"/storage", strings.NewReader(body)), user)
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		return rec
+	}
+	upload := func(size string) *httptest.ResponseRecorder {
+		body := `{"channelId":"` + channel.ID + `","filename":"vod.mp4","sizeBytes":` + size + `}`
+		req := withUser(httptest.NewRequest(http.MethodPost, "/api/uploads", strings.NewReader(body)), owner)
+		rec := httptest.NewRecorder()
+		handler.Uploads(rec, req)
+		return rec
+	}
+
+	if rec := storageRequest(http.MethodGet, viewer, ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected viewers to be refused, got %d", rec.Code)
+	}
+	if rec := storageRequest(http.MethodPatch, owner, `{"quotaBytes":1000}`); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected owners to be refused a quota change, got %d", rec.Code)
+	}
+	if rec := storageRequest(http.MethodPatch, admin, `{"quotaBytes":-1}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected a negative quota to be rejected, got %d", rec.Code)
+	}
+	rec := storageRequest(http.MethodPatch, admin, `{"quotaBytes":1000}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 from the quota change, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := upload("700"); rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201 for an upload within quota, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = upload("400")
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 for an upload past the quota, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var failure apiErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &failure); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if failure.Error.Code != "storage_quota_exceeded" {
+		t.Fatalf("expected the storage_quota_exceeded code, got %+v", failure.Error)
+	}
+
+	rec = storageRequest(http.MethodGet, owner, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var report channelStorageResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("decode storage: %v", err)
+	}
+	if report.UsedBytes != 700 || report.QuotaBytes != 1000 || report.QuotaOverride == nil || *report.QuotaOverride != 1000 {
+		t.Fatalf("unexpected storage report %+v", report)
+	}
+	if len(report.Items) != 1 || report.Items[0].Kind != storage.StorageItemUpload || report.Items[0].SizeBytes != 700 {
+		t.Fatalf("expected the upload in the breakdown, got %+v", report.Items)
+	}
+
+	rec = storageRequest(http.MethodPatch, admin, `{"quotaBytes":null}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 from clearing the override, got %d: %s", rec.Code, rec.Body.String())
+	}
+	report = channelStorageResponse{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &report); err != nil {
+		t.Fatalf("decode storage: %v", err)
+	}
+	if report.QuotaOverride != nil || report.QuotaBytes != 0 {
+		t.Fatalf("expected the platform default (unlimited), got %+v", report)
+	}
+	if rec := upload("400"); rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201 once the quota is lifted, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
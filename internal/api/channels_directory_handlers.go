@@ -7,6 +7,7 @@ import (
 	"strings"
 	"time"
 
+	"bitriver-live/internal/authz"
 	"bitriver-live/internal/models"
 	"bitriver-live/internal/observability/metrics"
 	"bitriver-live/internal/storage"
@@ -20,26 +21,80 @@ type createChannelRequest struct {
 }
 
 type updateChannelRequest struct {
-	Title    *string   `json:"title"`
-	Category *string   `json:"category"`
-	Tags     *[]string `json:"tags"`
+	Title               *string   `json:"title"`
+	Category            *string   `json:"category"`
+	Tags                *[]string `json:"tags"`
+	PlaybackRestriction *string   `json:"playbackRestriction"`
+	PlaybackPreviews    *bool     `json:"playbackPreviews"`
+	RecordingPolicy     *string   `json:"recordingPolicy"`
+	MatureContent       *bool     `json:"matureContent"`
+	// Language is a BCP-47 tag from models.SupportedChannelLanguages; an
+	// empty string clears it. ContentWarnings replaces the channel's content
+	// warnings with values from models.ContentWarnings.
+	Language        *string   `json:"language"`
+	ContentWarnings *[]string `json:"contentWarnings"`
+	// TranscodeLimits overrides the platform transcode caps for the channel.
+	// Only platform managers may set it; all-zero values clear the override.
+	TranscodeLimits *models.TranscodeLimits `json:"transcodeLimits"`
+	// ChatMaxCapsPercent and ChatRestrictLinks configure how chat messages
+	// are processed; see chat.ProcessContent. ChatSubscribersOnly limits
+	// chat to subscribers and moderators. ChatWelcomeMessage is shown to
+	// viewers joining the chat; an empty string clears it.
+	ChatMaxCapsPercent  *int    `json:"chatMaxCapsPercent"`
+	ChatRestrictLinks   *bool   `json:"chatRestrictLinks"`
+	ChatSubscribersOnly *bool   `json:"chatSubscribersOnly"`
+	ChatWelcomeMessage  *string `json:"chatWelcomeMessage"`
+	// Slug replaces the channel's URL slug; see storage.ChangeChannelSlug
+	// for the rules and how often it may change.
+	Slug *string `json:"slug"`
 }
 
 type channelPublicResponse struct {
 	ID               string   `json:"id"`
 	OwnerID          string   `json:"ownerId"`
+	Slug             string   `json:"slug"`
 	Title            string   `json:"title"`
 	Category         string   `json:"category,omitempty"`
 	Tags             []string `json:"tags"`
 	LiveState        string   `json:"liveState"`
 	CurrentSessionID *string  `json:"currentSessionId,omitempty"`
+	StartingSince    *string  `json:"startingSince,omitempty"`
 	CreatedAt        string   `json:"createdAt"`
 	UpdatedAt        string   `json:"updatedAt"`
+	// PlaybackRestriction tells players why playback may be withheld.
+	PlaybackRestriction string `json:"playbackRestriction,omitempty"`
+	// MatureContent lets clients blur thumbnails and prompt for age
+	// confirmation.
+	MatureContent bool `json:"matureContent,omitempty"`
+	// Language and ContentWarnings let clients label the channel and filter
+	// the directory.
+	Language        string   `json:"language,omitempty"`
+	ContentWarnings []string `json:"contentWarnings,omitempty"`
+	// ChatSubscribersOnly lets clients explain why non-subscribers cannot
+	// chat.
+	ChatSubscribersOnly bool `json:"chatSubscribersOnly,omitempty"`
+	// ChatWelcomeMessage is shown by clients to each viewer joining the
+	// chat.
+	ChatWelcomeMessage string `json:"chatWelcomeMessage,omitempty"`
 }
 
 type channelResponse struct {
 	channelPublicResponse
-	StreamKey string `json:"streamKey"`
+	PlaybackPreviews bool   `json:"playbackPreviews"`
+	RecordingPolicy  string `json:"recordingPolicy"`
+	StreamKeyHint    string `json:"streamKeyHint,omitempty"`
+	// SlugChangedAt is when the owner last chose the slug, absent while it
+	// is still the one derived from the title.
+	SlugChangedAt *string `json:"slugChangedAt,omitempty"`
+	// StreamKey and StreamKeyNotice are only set when the channel is created
+	// or its key rotated; the plaintext key cannot be retrieved afterwards.
+	StreamKey       string `json:"streamKey,omitempty"`
+	StreamKeyNotice string `json:"streamKeyNotice,omitempty"`
+	// TranscodeLimits is the channel's override of the platform transcode
+	// caps, absent when the platform defaults apply.
+	TranscodeLimits    *models.TranscodeLimits `json:"transcodeLimits,omitempty"`
+	ChatMaxCapsPercent int                     `json:"chatMaxCapsPercent,omitempty"`
+	ChatRestrictLinks  bool                    `json:"chatRestrictLinks,omitempty"`
 }
 
 type channelOwnerResponse struct {
@@ -61,6 +116,14 @@ type directoryChannelResponse struct {
 	Profile       profileSummaryResponse `json:"profile"`
 	Live          bool                   `json:"live"`
 	FollowerCount int                    `json:"followerCount"`
+	// PreviewThumbnailURL is a recent frame of a live stream, omitted until
+	// the transcoder has captured one.
+	PreviewThumbnailURL string `json:"previewThumbnailUrl,omitempty"`
+	// Premiere is set while the channel premieres a scheduled recording.
+	Premiere *directoryPremiereResponse `json:"premiere,omitempty"`
+	// CoStream is set while the channel mirrors a host's live session as
+	// an accepted co-stream guest.
+	CoStream *coStreamResponse `json:"coStream,omitempty"`
 }
 
 type directoryResponse struct {
@@ -99,31 +162,60 @@ type playbackStreamResponse struct {
 	PlayerHint  string                      `json:"playerHint,omitempty"`
 	LatencyMode string                      `json:"latencyMode,omitempty"`
 	Renditions  []renditionManifestResponse `json:"renditions,omitempty"`
+	// Endpoints lists every protocol the stream is served over, most
+	// preferred first. Players try each in turn and fall back to
+	// PlaybackURL when none is usable.
+	Endpoints []playbackEndpointResponse `json:"endpoints,omitempty"`
+	// PreferredRendition names the rendition the viewer's default quality
+	// maps to. Empty leaves the choice to adaptive bitrate.
+	PreferredRendition string `json:"preferredRendition,omitempty"`
+	// TokenExpiresAt is set when the URLs carry a signed playback token;
+	// players refetch playback before it passes.
+	TokenExpiresAt *string `json:"tokenExpiresAt,omitempty"`
 }
 
 type channelPlaybackResponse struct {
-	Channel           channelPublicResponse      `json:"channel"`
-	Owner             channelOwnerResponse       `json:"owner"`
-	Profile           profileSummaryResponse     `json:"profile"`
-	DonationAddresses []cryptoAddressResponse    `json:"donationAddresses"`
-	Live              bool                       `json:"live"`
-	Follow            followStateResponse        `json:"follow"`
-	Subscription      *subscriptionStateResponse `json:"subscription,omitempty"`
-	Playback          *playbackStreamResponse    `json:"playback,omitempty"`
+	Channel           channelPublicResponse   `json:"channel"`
+	Owner             channelOwnerResponse    `json:"owner"`
+	Profile           profileSummaryResponse  `json:"profile"`
+	DonationAddresses []cryptoAddressResponse `json:"donationAddresses"`
+	Live              bool                    `json:"live"`
+	// StreamState tells a provisioned stream still waiting for media
+	// ("starting") and one whose encoder dropped out ("interrupted") apart
+	// from one that is "live".
+	StreamState  string                     `json:"streamState"`
+	Follow       followStateResponse        `json:"follow"`
+	Subscription *subscriptionStateResponse `json:"subscription,omitempty"`
+	Playback     *playbackStreamResponse    `json:"playback,omitempty"`
+	// PlaybackPreferences are the viewer's own, so the payload is never
+	// shared between viewers.
+	PlaybackPreferences playbackPreferencesResponse `json:"playbackPreferences"`
+	// PlaybackWithheld is set when the channel is live but the viewer does
+	// not satisfy its age gate or playback restriction, and
+	// PlaybackWithheldReason says which.
+	PlaybackWithheld       bool   `json:"playbackWithheld,omitempty"`
+	PlaybackWithheldReason string `json:"playbackWithheldReason,omitempty"`
+	// CoStream is set when the channel is offline but co-streaming, in
+	// which case Playback and StreamState describe the host's session.
+	CoStream *coStreamResponse `json:"coStream,omitempty"`
 }
 
 type vodItemResponse struct {
-	ID              string  `json:"id"`
-	Title           string  `json:"title"`
-	DurationSeconds int     `json:"durationSeconds"`
-	PublishedAt     *string `json:"publishedAt,omitempty"`
-	ThumbnailURL    string  `json:"thumbnailUrl,omitempty"`
-	PlaybackURL     string  `json:"playbackUrl,omitempty"`
+	ID              string   `json:"id"`
+	Title           string   `json:"title"`
+	DurationSeconds int      `json:"durationSeconds"`
+	Tags            []string `json:"tags,omitempty"`
+	PublishedAt     *string  `json:"publishedAt,omitempty"`
+	ThumbnailURL    string   `json:"thumbnailUrl,omitempty"`
+	PlaybackURL     string   `json:"playbackUrl,omitempty"`
 }
 
 type vodCollectionResponse struct {
 	ChannelID string            `json:"channelId"`
 	Items     []vodItemResponse `json:"items"`
+	// PlaybackWithheldReason is set when the items' playback URLs were
+	// removed because the viewer has not passed the channel's age gate.
+	PlaybackWithheldReason string `json:"playbackWithheldReason,omitempty"`
 }
 
 func (h *Handler) Directory(w http.ResponseWriter, r *http.Request) {
@@ -132,12 +224,40 @@ func (h *Handler) Directory(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	query := ""
-	if r.URL != nil {
-		query = strings.TrimSpace(r.URL.Query().Get("q"))
+	filter := directoryFilter(r)
+	key := "search:" + filter.Query
+	if filter.Language != "" || len(filter.ExcludeWarnings) > 0 {
+		key += "|language:" + filter.Language + "|exclude:" + strings.Join(filter.ExcludeWarnings, ",")
 	}
-	channels := h.Store.ListChannels("", query)
-	h.writeDirectoryResponse(w, channels)
+	h.writeCachedJSON(w, r, sharedETag("directory"), directoryCacheNamespace, key, func() (interface{}, error) {
+		channels, err := h.Store.ListChannelsMatching(filter)
+		if err != nil {
+			return nil, err
+		}
+		return h.buildDirectoryResponse(channels), nil
+	})
+}
+
+// directoryFilter reads the directory's search and filter parameters: q,
+// language, and excludeWarnings, which may be repeated or comma separated.
+// Channels without a language stay listed unless language is set.
+func directoryFilter(r *http.Request) storage.ChannelFilter {
+	var filter storage.ChannelFilter
+	if r.URL == nil {
+		return filter
+	}
+	values := r.URL.Query()
+	filter.Query = strings.TrimSpace(values.Get("q"))
+	filter.Language = strings.TrimSpace(values.Get("language"))
+	for _, value := range values["excludeWarnings"] {
+		for _, warning := range strings.Split(value, ",") {
+			if warning = strings.ToLower(strings.TrimSpace(warning)); warning != "" {
+				filter.ExcludeWarnings = append(filter.ExcludeWarnings, warning)
+			}
+		}
+	}
+	sort.Strings(filter.ExcludeWarnings)
+	return filter
 }
 
 func (h *Handler) DirectoryFeatured(w http.ResponseWriter, r *http.Request) {
@@ -146,7 +266,20 @@ func (h *Handler) DirectoryFeatured(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	profiles := h.Store.ListProfiles()
+	h.writeCachedJSON(w, r, sharedETag("directory_featured"), directoryCacheNamespace, "featured", func() (interface{}, error) {
+		channels, err := h.featuredChannels()
+		if err != nil {
+			return nil, err
+		}
+		return h.buildDirectoryResponse(channels), nil
+	})
+}
+
+func (h *Handler) featuredChannels() ([]models.Channel, error) {
+	profiles, err := h.Store.ListProfiles()
+	if err != nil {
+		return nil, err
+	}
 	channelIDs := make(map[string]struct{}, len(profiles))
 	for _, profile := range profiles {
 		if profile.FeaturedChannelID == nil {
@@ -166,7 +299,7 @@ func (h *Handler) DirectoryFeatured(w http.ResponseWriter, r *http.Request) {
 		}
 	}
 
-	h.writeDirectoryResponse(w, h.sortChannelsByFollowers(channels, true))
+	return h.sortChannelsByFollowers(channels, true), nil
 }
 
 func (h *Handler) DirectoryRecommended(w http.ResponseWriter, r *http.Request) {
@@ -175,8 +308,13 @@ func (h *Handler) DirectoryRecommended(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	channels := h.Store.ListChannels("", "")
-	h.writeDirectoryResponse(w, h.sortChannelsByFollowers(channels, false))
+	h.writeCachedJSON(w, r, sharedETag("directory_recommended"), directoryCacheNamespace, "recommended", func() (interface{}, error) {
+		channels, err := h.Store.ListChannels("", "")
+		if err != nil {
+			return nil, err
+		}
+		return h.buildDirectoryResponse(h.sortChannelsByFollowers(channels, false)), nil
+	})
 }
 
 func (h *Handler) DirectoryLive(w http.ResponseWriter, r *http.Request) {
@@ -185,9 +323,13 @@ func (h *Handler) DirectoryLive(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	channels := h.Store.ListChannels("", "")
-	channels = filterLiveChannels(channels)
-	h.writeDirectoryResponse(w, h.sortChannelsByFollowers(channels, true))
+	h.writeCachedJSON(w, r, sharedETag("directory_live"), directoryCacheNamespace, "live", func() (interface{}, error) {
+		channels, err := h.liveChannels()
+		if err != nil {
+			return nil, err
+		}
+		return h.buildDirectoryResponse(h.sortChannelsByFollowers(channels, true)), nil
+	})
 }
 
 func (h *Handler) DirectoryTrending(w http.ResponseWriter, r *http.Request) {
@@ -196,8 +338,13 @@ func (h *Handler) DirectoryTrending(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	channels := filterLiveChannels(h.Store.ListChannels("", ""))
-	h.writeDirectoryResponse(w, h.sortChannelsByFollowers(channels, true))
+	h.writeCachedJSON(w, r, sharedETag("directory_trending"), directoryCacheNamespace, "trending", func() (interface{}, error) {
+		channels, err := h.liveChannels()
+		if err != nil {
+			return nil, err
+		}
+		return h.buildDirectoryResponse(h.sortChannelsByFollowers(channels, true)), nil
+	})
 }
 
 func (h *Handler) DirectoryCategories(w http.ResponseWriter, r *http.Request) {
@@ -206,7 +353,21 @@ func (h *Handler) DirectoryCategories(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	channels := filterLiveChannels(h.Store.ListChannels("", ""))
+	h.writeCachedJSON(w, r, sharedETag("directory_categories"), directoryCacheNamespace, "categories", func() (interface{}, error) {
+		return h.buildCategoryDirectoryResponse()
+	})
+}
+
+// etagContent leaves GeneratedAt out of the category directory's ETag.
+func (r categoryDirectoryResponse) etagContent() interface{} {
+	return r.Categories
+}
+
+func (h *Handler) buildCategoryDirectoryResponse() (categoryDirectoryResponse, error) {
+	channels, err := h.liveChannels()
+	if err != nil {
+		return categoryDirectoryResponse{}, err
+	}
 	counts := make(map[string]int)
 	for _, channel := range channels {
 		category := strings.TrimSpace(channel.Category)
@@ -227,8 +388,18 @@ func (h *Handler) DirectoryCategories(w http.ResponseWriter, r *http.Request) {
 		return summaries[i].ChannelCount > summaries[j].ChannelCount
 	})
 
-	payload := categoryDirectoryResponse{Categories: summaries, GeneratedAt: time.Now().UTC().Format(time.RFC3339Nano)}
-	WriteJSON(w, http.StatusOK, payload)
+	return categoryDirectoryResponse{Categories: summaries, GeneratedAt: time.Now().UTC().Format(time.RFC3339Nano)}, nil
+}
+
+// liveChannels lists the channels that are live or starting, followed by
+// the guests co-streaming one of them.
+func (h *Handler) liveChannels() ([]models.Channel, error) {
+	channels, err := h.Store.ListChannels("", "")
+	if err != nil {
+		return nil, err
+	}
+	live := filterLiveChannels(channels)
+	return append(live, h.coStreamGuestChannels(channels, live)...), nil
 }
 
 func filterLiveChannels(channels []models.Channel) []models.Channel {
@@ -273,7 +444,11 @@ func (h *Handler) DirectoryFollowing(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	channelIDs := h.Store.ListFollowedChannelIDs(viewer.ID)
+	channelIDs, err := h.Store.ListFollowedChannelIDs(viewer.ID)
+	if err != nil {
+		writeStorageError(w, http.StatusInternalServerError, err)
+		return
+	}
 	channels := make([]models.Channel, 0, len(channelIDs))
 	for _, id := range channelIDs {
 		channel, exists := h.Store.GetChannel(id)
@@ -286,10 +461,18 @@ func (h *Handler) DirectoryFollowing(w http.ResponseWriter, r *http.Request) {
 		channels = append(channels, channel)
 	}
 
-	h.writeDirectoryResponse(w, channels)
+	h.writeETagJSON(w, r, viewerETag("directory_following"), h.buildDirectoryResponse(channels))
+}
+
+// etagContent leaves GeneratedAt out of the directory's ETag.
+func (r directoryResponse) etagContent() interface{} {
+	return r.Channels
 }
 
-func (h *Handler) writeDirectoryResponse(w http.ResponseWriter, channels []models.Channel) {
+func (h *Handler) buildDirectoryResponse(channels []models.Channel) directoryResponse {
+	now := h.now()
+	premieres := h.premieresByChannel(now)
+	coStreams := h.activeCoStreams()
 	response := make([]directoryChannelResponse, 0, len(channels))
 	for _, channel := range channels {
 		owner, exists := h.Store.GetUser(channel.OwnerID)
@@ -298,41 +481,87 @@ func (h *Handler) writeDirectoryResponse(w http.ResponseWriter, channels []model
 		}
 		profile, _ := h.Store.GetProfile(owner.ID)
 		followerCount := h.Store.CountFollowers(channel.ID)
-		response = append(response, directoryChannelResponse{
+		entry := directoryChannelResponse{
 			Channel:       newChannelPublicResponse(channel),
 			Owner:         newOwnerResponse(owner, profile),
 			Profile:       newProfileSummaryResponse(profile),
 			Live:          channel.LiveState == "live" || channel.LiveState == "starting",
 			FollowerCount: followerCount,
-		})
+			Premiere:      premieres[channel.ID],
+		}
+		if channel.LiveState == "live" && channel.PlaybackRestriction == "" {
+			if session, ok := h.Store.CurrentStreamSession(channel.ID); ok {
+				entry.PreviewThumbnailURL = livePreviewThumbnailURL(session, now)
+			}
+		}
+		if invite, ok := coStreams[channel.ID]; ok && !entry.Live {
+			if host, ok := h.Store.GetChannel(invite.HostChannelID); ok {
+				entry.Live = true
+				entry.CoStream = h.newCoStreamResponse(invite, host)
+			}
+		}
+		response = append(response, entry)
 	}
 
-	payload := directoryResponse{
+	return directoryResponse{
 		Channels:    response,
 		GeneratedAt: time.Now().UTC().Format(time.RFC3339Nano),
 	}
-	WriteJSON(w, http.StatusOK, payload)
 }
 
+// streamKeyNotice accompanies the only response that carries a plaintext
+// stream key.
+const streamKeyNotice = "Copy this stream key now. Only a hash is stored, so it cannot be shown again; rotate the key to get a new one."
+
 func buildChannelResponse(channel models.Channel, includeStreamKey bool) channelResponse {
 	resp := channelResponse{
 		channelPublicResponse: channelPublicResponse{
-			ID:        channel.ID,
-			OwnerID:   channel.OwnerID,
-			Title:     channel.Title,
-			Category:  channel.Category,
-			Tags:      append([]string{}, channel.Tags...),
-			LiveState: channel.LiveState,
-			CreatedAt: channel.CreatedAt.Format(time.RFC3339Nano),
-			UpdatedAt: channel.UpdatedAt.Format(time.RFC3339Nano),
+			ID:                  channel.ID,
+			OwnerID:             channel.OwnerID,
+			Slug:                channel.Slug,
+			Title:               channel.Title,
+			Category:            channel.Category,
+			Tags:                append([]string{}, channel.Tags...),
+			LiveState:           channel.LiveState,
+			CreatedAt:           channel.CreatedAt.Format(time.RFC3339Nano),
+			UpdatedAt:           channel.UpdatedAt.Format(time.RFC3339Nano),
+			PlaybackRestriction: channel.PlaybackRestriction,
+			MatureContent:       channel.MatureContent,
+			Language:            channel.Language,
+			ContentWarnings:     append([]string(nil), channel.ContentWarnings...),
+			ChatSubscribersOnly: channel.ChatSubscribersOnly,
+			ChatWelcomeMessage:  channel.ChatWelcomeMessage,
 		},
+		PlaybackPreviews:   channel.PlaybackPreviews,
+		RecordingPolicy:    channel.RecordingPolicy,
+		ChatMaxCapsPercent: channel.ChatMaxCapsPercent,
+		ChatRestrictLinks:  channel.ChatRestrictLinks,
+	}
+	if includeStreamKey && channel.TranscodeLimits != nil {
+		limits := *channel.TranscodeLimits
+		resp.TranscodeLimits = &limits
+	}
+	if resp.RecordingPolicy == "" {
+		resp.RecordingPolicy = models.RecordingPolicyManual
 	}
 	if channel.CurrentSessionID != nil {
 		sessionID := *channel.CurrentSessionID
 		resp.CurrentSessionID = &sessionID
 	}
+	if channel.StartingSince != nil {
+		since := channel.StartingSince.Format(time.RFC3339Nano)
+		resp.StartingSince = &since
+	}
 	if includeStreamKey {
-		resp.StreamKey = channel.StreamKey
+		resp.StreamKeyHint = channel.StreamKeyHint
+		if channel.SlugChangedAt != nil {
+			changed := channel.SlugChangedAt.Format(time.RFC3339Nano)
+			resp.SlugChangedAt = &changed
+		}
+		if channel.StreamKey != "" {
+			resp.StreamKey = channel.StreamKey
+			resp.StreamKeyNotice = streamKeyNotice
+		}
 	}
 	return resp
 }
@@ -406,17 +635,22 @@ func (h *Handler) Channels(w http.ResponseWriter, r *http.Request) {
 			return
 		}
 		ownerID := strings.TrimSpace(r.URL.Query().Get("ownerId"))
+		manageAny := authz.Has(actor, authz.ChannelsManageAny)
 		if ownerID == "" {
-			if !actor.HasRole(roleAdmin) {
+			if !manageAny {
 				ownerID = actor.ID
 			}
-		} else if ownerID != actor.ID && !actor.HasRole(roleAdmin) {
+		} else if ownerID != actor.ID && !manageAny {
 			WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
 			return
 		}
 
-		channels := h.Store.ListChannels(ownerID, "")
-		if ownerID == actor.ID || actor.HasRole(roleAdmin) {
+		channels, err := h.Store.ListChannels(ownerID, "")
+		if err != nil {
+			writeStorageError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if ownerID == actor.ID || manageAny {
 			response := make([]channelResponse, 0, len(channels))
 			for _, channel := range channels {
 				response = append(response, newChannelResponse(channel))
@@ -431,10 +665,13 @@ func (h *Handler) Channels(w http.ResponseWriter, r *http.Request) {
 		}
 		WriteJSON(w, http.StatusOK, response)
 	case http.MethodPost:
-		actor, ok := h.requireRole(w, r, roleAdmin, roleCreator)
+		actor, ok := h.requirePermission(w, r, authz.ChannelsCreate)
 		if !ok {
 			return
 		}
+		if !h.requireScope(w, r, storage.APITokenScopeManageChannel) {
+			return
+		}
 		var req createChannelRequest
 		if !DecodeAndValidate(w, r, &req) {
 			return
@@ -442,15 +679,16 @@ func (h *Handler) Channels(w http.ResponseWriter, r *http.Request) {
 		if req.OwnerID == "" {
 			req.OwnerID = actor.ID
 		}
-		if req.OwnerID != actor.ID && !actor.HasRole(roleAdmin) {
+		if req.OwnerID != actor.ID && !authz.Has(actor, authz.ChannelsManageAny) {
 			WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
 			return
 		}
 		channel, err := h.Store.CreateChannel(req.OwnerID, req.Title, req.Category, req.Tags)
 		if err != nil {
-			WriteError(w, http.StatusBadRequest, err)
+			writeStorageError(w, http.StatusBadRequest, err)
 			return
 		}
+		h.invalidateDirectoryCache(r.Context())
 		WriteJSON(w, http.StatusCreated, newChannelResponse(channel))
 	default:
 		WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodPost)
@@ -469,32 +707,46 @@ func (h *Handler) ChannelByID(w http.ResponseWriter, r *http.Request) {
 	}
 	channelID := parts[0]
 
+	if len(parts) == 1 && channelID == "status" {
+		h.handleChannelStatuses(w, r)
+		return
+	}
+	if len(parts) == 2 && channelID == "by-slug" {
+		h.channelBySlug(w, r, parts[1])
+		return
+	}
+
 	if len(parts) == 1 {
 		switch r.Method {
 		case http.MethodGet:
-			channel, ok := h.Store.GetChannel(channelID)
-			if !ok {
-				WriteError(w, http.StatusNotFound, fmt.Errorf("channel %s not found", channelID))
-				return
-			}
-			if actor, ok := UserFromContext(r.Context()); ok && (channel.OwnerID == actor.ID || actor.HasRole(roleAdmin)) {
-				WriteJSON(w, http.StatusOK, newChannelResponse(channel))
-				return
-			}
-			WriteJSON(w, http.StatusOK, newChannelPublicResponse(channel))
+			h.writeChannel(w, r, channelID)
 		case http.MethodPatch:
 			channel, ok := h.Store.GetChannel(channelID)
 			if !ok {
 				WriteError(w, http.StatusNotFound, fmt.Errorf("channel %s not found", channelID))
 				return
 			}
-			if _, ok := h.ensureChannelAccess(w, r, channel); !ok {
+			actor, ok := h.ensureChannelAccess(w, r, channel)
+			if !ok {
 				return
 			}
 			var req updateChannelRequest
 			if !DecodeAndValidate(w, r, &req) {
 				return
 			}
+			if req.TranscodeLimits != nil && !authz.Has(actor, authz.PlatformManage) {
+				WriteError(w, http.StatusForbidden, fmt.Errorf("only platform administrators can change transcode limits"))
+				return
+			}
+			if req.Slug != nil {
+				if _, err := h.Store.ChangeChannelSlug(channelID, *req.Slug, h.now()); err != nil {
+					if !writeChannelSlugError(w, err) {
+						writeStorageError(w, http.StatusBadRequest, err)
+					}
+					return
+				}
+				h.invalidateChannelCache(r.Context(), channelID)
+			}
 			update := storage.ChannelUpdate{}
 			if req.Title != nil {
 				update.Title = req.Title
@@ -506,11 +758,51 @@ func (h *Handler) ChannelByID(w http.ResponseWriter, r *http.Request) {
 				tagsCopy := append([]string{}, (*req.Tags)...)
 				update.Tags = &tagsCopy
 			}
+			if req.PlaybackRestriction != nil {
+				if restrictsPlayback(*req.PlaybackRestriction) && h.PlaybackSigner == nil {
+					WriteRequestError(w, ValidationError("signed playback is not configured on this server"))
+					return
+				}
+				update.PlaybackRestriction = req.PlaybackRestriction
+			}
+			if req.PlaybackPreviews != nil {
+				update.PlaybackPreviews = req.PlaybackPreviews
+			}
+			if req.RecordingPolicy != nil {
+				update.RecordingPolicy = req.RecordingPolicy
+			}
+			if req.MatureContent != nil {
+				update.MatureContent = req.MatureContent
+			}
+			if req.Language != nil {
+				update.Language = req.Language
+			}
+			if req.ContentWarnings != nil {
+				warnings := append([]string{}, (*req.ContentWarnings)...)
+				update.ContentWarnings = &warnings
+			}
+			if req.TranscodeLimits != nil {
+				limits := *req.TranscodeLimits
+				update.TranscodeLimits = &limits
+			}
+			if req.ChatMaxCapsPercent != nil {
+				update.ChatMaxCapsPercent = req.ChatMaxCapsPercent
+			}
+			if req.ChatRestrictLinks != nil {
+				update.ChatRestrictLinks = req.ChatRestrictLinks
+			}
+			if req.ChatSubscribersOnly != nil {
+				update.ChatSubscribersOnly = req.ChatSubscribersOnly
+			}
+			if req.ChatWelcomeMessage != nil {
+				update.ChatWelcomeMessage = req.ChatWelcomeMessage
+			}
 			channel, err := h.Store.UpdateChannel(channelID, update)
 			if err != nil {
-				WriteError(w, http.StatusBadRequest, err)
+				writeStorageError(w, http.StatusBadRequest, err)
 				return
 			}
+			h.invalidateChannelCache(r.Context(), channelID)
 			WriteJSON(w, http.StatusOK, newChannelResponse(channel))
 		case http.MethodDelete:
 			channel, ok := h.Store.GetChannel(channelID)
@@ -522,9 +814,10 @@ func (h *Handler) ChannelByID(w http.ResponseWriter, r *http.Request) {
 				return
 			}
 			if err := h.Store.DeleteChannel(channelID); err != nil {
-				WriteError(w, http.StatusBadRequest, err)
+				writeStorageError(w, http.StatusBadRequest, err)
 				return
 			}
+			h.invalidateChannelCache(r.Context(), channelID)
 			w.WriteHeader(http.StatusNoContent)
 		default:
 			WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodPatch, http.MethodDelete)
@@ -556,30 +849,49 @@ func (h *Handler) ChannelByID(w http.ResponseWriter, r *http.Request) {
 				follow.Following = h.Store.IsFollowingChannel(actor.ID, channel.ID)
 				viewer = &actor
 			}
-			donations := make([]cryptoAddressResponse, 0, len(profile.DonationAddresses))
-			for _, addr := range profile.DonationAddresses {
-				donations = append(donations, cryptoAddressResponse{
-					Currency: addr.Currency,
-					Address:  addr.Address,
-					Note:     addr.Note,
-				})
-			}
-
 			response := channelPlaybackResponse{
 				Channel:           newChannelPublicResponse(channel),
 				Owner:             newOwnerResponse(owner, profile),
 				Profile:           newProfileSummaryResponse(profile),
-				DonationAddresses: donations,
+				DonationAddresses: newCryptoAddressResponses(profile.DonationAddresses),
 				Live:              channel.LiveState == "live" || channel.LiveState == "starting",
 				Follow:            follow,
 			}
 			if state, err := h.subscriptionState(channel.ID, viewer); err == nil {
 				response.Subscription = &state
 			} else {
-				WriteError(w, http.StatusInternalServerError, err)
+				writeStorageError(w, http.StatusInternalServerError, err)
+				return
+			}
+			prefs, err := h.viewerPlaybackPreferences(r, viewer)
+			if err != nil {
+				writeStorageError(w, http.StatusInternalServerError, err)
 				return
 			}
-			if session, live := h.Store.CurrentStreamSession(channel.ID); live {
+			response.PlaybackPreferences = newPlaybackPreferencesResponse(prefs)
+			session, live := h.Store.CurrentStreamSession(channel.ID)
+			// An offline guest of a co-stream plays the host's session under
+			// the host's gating.
+			source := channel
+			following, subscribed := follow.Following, response.Subscription != nil && response.Subscription.Subscribed
+			if !live {
+				if invite, host, hostSession, ok := h.coStreamSource(channel.ID); ok {
+					source, session, live = host, hostSession, true
+					response.Live = true
+					response.CoStream = h.newCoStreamResponse(invite, host)
+					if viewer != nil {
+						following = h.Store.IsFollowingChannel(viewer.ID, host.ID)
+						state, err := h.subscriptionState(host.ID, viewer)
+						if err != nil {
+							writeStorageError(w, http.StatusInternalServerError, err)
+							return
+						}
+						subscribed = state.Subscribed
+					}
+				}
+			}
+			response.StreamState = h.streamState(source, session, live)
+			if live {
 				playback := playbackStreamResponse{
 					SessionID: session.ID,
 					StartedAt: session.StartedAt.Format(time.RFC3339Nano),
@@ -601,53 +913,101 @@ func (h *Handler) ChannelByID(w http.ResponseWriter, r *http.Request) {
 					}
 					playback.Renditions = manifests
 				}
+				playback.PreferredRendition = preferredRendition(prefs.DefaultQuality, playback.Renditions)
+				playback.Endpoints = newPlaybackEndpointResponses(session.PlaybackEndpoints)
 				protocol := "ll-hls"
 				player := "hls.js"
-				latency := "low-latency"
-				url := strings.ToLower(playback.PlaybackURL)
-				if strings.HasPrefix(url, "webrtc") || strings.HasPrefix(url, "wss") {
+				latency := models.LatencyModeForPlaybackURL(playback.PlaybackURL)
+				if len(session.PlaybackEndpoints) > 0 && session.PlaybackEndpoints[0].Protocol == models.PlaybackProtocolWebRTC {
+					latency = models.LatencyModeUltraLow
+				}
+				if latency == models.LatencyModeUltraLow {
 					protocol = "webrtc"
 					player = "ovenplayer"
-					latency = "ultra-low"
 				}
 				playback.Protocol = protocol
 				playback.PlayerHint = player
 				playback.LatencyMode = latency
-				response.Playback = &playback
+				if source.MatureContent || source.PlaybackRestriction != "" {
+					w.Header().Set("Cache-Control", "no-store")
+				}
+				switch reason := ageGate(source, viewer, h.now()); {
+				case reason != "":
+					response.PlaybackWithheld = true
+					response.PlaybackWithheldReason = reason
+				case source.PlaybackRestriction != "" && !h.signPlayback(source, viewer, following, subscribed, &playback):
+					response.PlaybackWithheld = true
+					response.PlaybackWithheldReason = playbackWithheldRestricted
+				default:
+					response.Playback = &playback
+				}
 			}
-			WriteJSON(w, http.StatusOK, response)
+			// Gated and restricted playback must not be stored at all, and
+			// signed URLs change on every request anyway.
+			if w.Header().Get("Cache-Control") == "no-store" {
+				WriteJSON(w, http.StatusOK, response)
+				return
+			}
+			h.writeETagJSON(w, r, viewerETag("channel_playback"), response)
 			return
-		case "stream":
+		case "status":
+			if len(parts) > 2 {
+				WriteError(w, http.StatusNotFound, fmt.Errorf("unknown channel path"))
+				return
+			}
+			h.handleChannelStatus(channelID, w, r)
+			return
+		case "preview":
+			if len(parts) > 2 {
+				WriteError(w, http.StatusNotFound, fmt.Errorf("unknown channel path"))
+				return
+			}
 			channel, ok := h.Store.GetChannel(channelID)
 			if !ok {
 				WriteError(w, http.StatusNotFound, fmt.Errorf("channel %s not found", channelID))
 				return
 			}
-			h.handleStreamRoutes(channel, parts[2:], w, r)
+			h.handleChannelPreview(channel, w, r)
 			return
-		case "sessions":
+		case "clip":
+			if len(parts) > 2 {
+				WriteError(w, http.StatusNotFound, fmt.Errorf("unknown channel path"))
+				return
+			}
 			channel, ok := h.Store.GetChannel(channelID)
 			if !ok {
 				WriteError(w, http.StatusNotFound, fmt.Errorf("channel %s not found", channelID))
 				return
 			}
-			if _, ok := h.ensureChannelAccess(w, r, channel); !ok {
+			h.handleLiveClip(channel, w, r)
+			return
+		case "report":
+			if len(parts) > 2 {
+				WriteError(w, http.StatusNotFound, fmt.Errorf("unknown channel path"))
 				return
 			}
-			if r.Method != http.MethodGet {
-				WriteMethodNotAllowed(w, r, http.MethodGet)
+			channel, ok := h.Store.GetChannel(channelID)
+			if !ok {
+				WriteError(w, http.StatusNotFound, fmt.Errorf("channel %s not found", channelID))
 				return
 			}
-			sessions, err := h.Store.ListStreamSessions(channelID)
-			if err != nil {
-				WriteError(w, http.StatusBadRequest, err)
+			h.handleChannelReport(channel, w, r)
+			return
+		case "stream":
+			channel, ok := h.Store.GetChannel(channelID)
+			if !ok {
+				WriteError(w, http.StatusNotFound, fmt.Errorf("channel %s not found", channelID))
 				return
 			}
-			response := make([]sessionResponse, 0, len(sessions))
-			for _, session := range sessions {
-				response = append(response, newSessionResponse(session))
+			h.handleStreamRoutes(channel, parts[2:], w, r)
+			return
+		case "sessions":
+			channel, ok := h.Store.GetChannel(channelID)
+			if !ok {
+				WriteError(w, http.StatusNotFound, fmt.Errorf("channel %s not found", channelID))
+				return
 			}
-			WriteJSON(w, http.StatusOK, response)
+			h.handleChannelSessions(channel, parts[2:], w, r)
 			return
 		case "follow":
 			if len(parts) > 2 {
@@ -665,24 +1025,50 @@ func (h *Handler) ChannelByID(w http.ResponseWriter, r *http.Request) {
 			switch r.Method {
 			case http.MethodPost:
 				if err := h.Store.FollowChannel(actor.ID, channelID); err != nil {
-					WriteError(w, http.StatusBadRequest, err)
+					writeStorageError(w, http.StatusBadRequest, err)
 					return
 				}
 			case http.MethodDelete:
 				if err := h.Store.UnfollowChannel(actor.ID, channelID); err != nil {
-					WriteError(w, http.StatusBadRequest, err)
+					writeStorageError(w, http.StatusBadRequest, err)
 					return
 				}
 			default:
 				WriteMethodNotAllowed(w, r, http.MethodPost, http.MethodDelete)
 				return
 			}
+			h.invalidateDirectoryCache(r.Context())
+			r = readYourWrites(r)
 			state := followStateResponse{
-				Followers: h.Store.CountFollowers(channelID),
+				Followers: h.store(r).CountFollowers(channelID),
 				Following: h.Store.IsFollowingChannel(actor.ID, channelID),
 			}
 			WriteJSON(w, http.StatusOK, state)
 			return
+		case "followers":
+			if len(parts) > 2 {
+				WriteError(w, http.StatusNotFound, fmt.Errorf("unknown channel path"))
+				return
+			}
+			channel, ok := h.Store.GetChannel(channelID)
+			if !ok {
+				WriteError(w, http.StatusNotFound, fmt.Errorf("channel %s not found", channelID))
+				return
+			}
+			h.handleChannelFollowers(channel, w, r)
+			return
+		case "users":
+			if len(parts) != 4 || parts[2] == "" || parts[3] != "moderation-history" {
+				WriteError(w, http.StatusNotFound, fmt.Errorf("unknown channel path"))
+				return
+			}
+			channel, ok := h.Store.GetChannel(channelID)
+			if !ok {
+				WriteError(w, http.StatusNotFound, fmt.Errorf("channel %s not found", channelID))
+				return
+			}
+			h.handleModerationHistory(channel, parts[2], w, r)
+			return
 		case "subscribe":
 			if len(parts) > 2 {
 				WriteError(w, http.StatusNotFound, fmt.Errorf("unknown channel path"))
@@ -701,7 +1087,7 @@ func (h *Handler) ChannelByID(w http.ResponseWriter, r *http.Request) {
 				}
 				state, err := h.subscriptionState(channel.ID, viewer)
 				if err != nil {
-					WriteError(w, http.StatusBadRequest, err)
+					writeStorageError(w, http.StatusBadRequest, err)
 					return
 				}
 				WriteJSON(w, http.StatusOK, state)
@@ -712,7 +1098,7 @@ func (h *Handler) ChannelByID(w http.ResponseWriter, r *http.Request) {
 				}
 				subs, err := h.Store.ListSubscriptions(channel.ID, false)
 				if err != nil {
-					WriteError(w, http.StatusBadRequest, err)
+					writeStorageError(w, http.StatusBadRequest, err)
 					return
 				}
 				alreadySubscribed := false
@@ -735,14 +1121,15 @@ func (h *Handler) ChannelByID(w http.ResponseWriter, r *http.Request) {
 					}
 					sub, err := h.Store.CreateSubscription(params)
 					if err != nil {
-						WriteError(w, http.StatusBadRequest, err)
+						writeStorageError(w, http.StatusBadRequest, err)
 						return
 					}
 					metrics.Default().ObserveMonetization("subscription", sub.Amount)
+					h.invalidateChatAuthor(actor.ID)
 				}
 				state, err := h.subscriptionState(channel.ID, &actor)
 				if err != nil {
-					WriteError(w, http.StatusBadRequest, err)
+					writeStorageError(w, http.StatusBadRequest, err)
 					return
 				}
 				WriteJSON(w, http.StatusOK, state)
@@ -753,7 +1140,7 @@ func (h *Handler) ChannelByID(w http.ResponseWriter, r *http.Request) {
 				}
 				subs, err := h.Store.ListSubscriptions(channel.ID, false)
 				if err != nil {
-					WriteError(w, http.StatusBadRequest, err)
+					writeStorageError(w, http.StatusBadRequest, err)
 					return
 				}
 				subscriptionID := ""
@@ -765,13 +1152,14 @@ func (h *Handler) ChannelByID(w http.ResponseWriter, r *http.Request) {
 				}
 				if subscriptionID != "" {
 					if _, err := h.Store.CancelSubscription(subscriptionID, actor.ID, ""); err != nil {
-						WriteError(w, http.StatusBadRequest, err)
+						writeStorageError(w, http.StatusBadRequest, err)
 						return
 					}
+					h.invalidateChatAuthor(actor.ID)
 				}
 				state, err := h.subscriptionState(channel.ID, &actor)
 				if err != nil {
-					WriteError(w, http.StatusBadRequest, err)
+					writeStorageError(w, http.StatusBadRequest, err)
 					return
 				}
 				WriteJSON(w, http.StatusOK, state)
@@ -795,9 +1183,10 @@ func (h *Handler) ChannelByID(w http.ResponseWriter, r *http.Request) {
 			}
 			uploads, err := h.Store.ListUploads(channelID)
 			if err != nil {
-				WriteError(w, http.StatusBadRequest, err)
+				writeStorageError(w, http.StatusBadRequest, err)
 				return
 			}
+			tags := recordingTagFilter(r)
 			items := make([]vodItemResponse, 0, len(uploads))
 			for _, upload := range uploads {
 				if upload.RecordingID == nil {
@@ -807,7 +1196,7 @@ func (h *Handler) ChannelByID(w http.ResponseWriter, r *http.Request) {
 				if !ok {
 					continue
 				}
-				if recording.PublishedAt == nil {
+				if recording.PublishedAt == nil || !storage.RecordingHasTags(recording, tags) {
 					continue
 				}
 				item := newVodItemResponse(recording)
@@ -817,11 +1206,119 @@ func (h *Handler) ChannelByID(w http.ResponseWriter, r *http.Request) {
 				items = append(items, item)
 			}
 			payload := vodCollectionResponse{ChannelID: channel.ID, Items: items}
+			if channel.MatureContent {
+				var viewer *models.User
+				if actor, ok := UserFromContext(r.Context()); ok {
+					viewer = &actor
+				}
+				if reason := ageGate(channel, viewer, h.now()); reason != "" {
+					payload.PlaybackWithheldReason = reason
+					for i := range payload.Items {
+						payload.Items[i].PlaybackURL = ""
+					}
+				}
+				w.Header().Set("Cache-Control", "no-store")
+			}
 			WriteJSON(w, http.StatusOK, payload)
 			return
+		case "editors":
+			channel, ok := h.Store.GetChannel(channelID)
+			if !ok {
+				WriteError(w, http.StatusNotFound, fmt.Errorf("channel %s not found", channelID))
+				return
+			}
+			h.handleChannelEditors(channel, parts[2:], w, r)
+			return
+		case "moderators":
+			channel, ok := h.Store.GetChannel(channelID)
+			if !ok {
+				WriteError(w, http.StatusNotFound, fmt.Errorf("channel %s not found", channelID))
+				return
+			}
+			h.handleChannelModerators(channel, parts[2:], w, r)
+			return
+		case "restreams":
+			channel, ok := h.Store.GetChannel(channelID)
+			if !ok {
+				WriteError(w, http.StatusNotFound, fmt.Errorf("channel %s not found", channelID))
+				return
+			}
+			h.handleRestreamTargets(channel, parts[2:], w, r)
+			return
+		case "costream":
+			channel, ok := h.Store.GetChannel(channelID)
+			if !ok {
+				WriteError(w, http.StatusNotFound, fmt.Errorf("channel %s not found", channelID))
+				return
+			}
+			h.handleCoStreamInvites(channel, parts[2:], w, r)
+			return
+		case "schedule":
+			channel, ok := h.Store.GetChannel(channelID)
+			if !ok {
+				WriteError(w, http.StatusNotFound, fmt.Errorf("channel %s not found", channelID))
+				return
+			}
+			h.handleChannelSchedule(channel, parts[2:], w, r)
+			return
+		case "subscription":
+			channel, ok := h.Store.GetChannel(channelID)
+			if !ok {
+				WriteError(w, http.StatusNotFound, fmt.Errorf("channel %s not found", channelID))
+				return
+			}
+			h.handleChannelSubscription(channel, parts[2:], w, r)
+			return
+		case "recordings":
+			channel, ok := h.Store.GetChannel(channelID)
+			if !ok {
+				WriteError(w, http.StatusNotFound, fmt.Errorf("channel %s not found", channelID))
+				return
+			}
+			h.handleChannelRecordings(channel, parts[2:], w, r)
+			return
+		case "storage":
+			channel, ok := h.Store.GetChannel(channelID)
+			if !ok {
+				WriteError(w, http.StatusNotFound, fmt.Errorf("channel %s not found", channelID))
+				return
+			}
+			h.handleChannelStorage(channel, parts[2:], w, r)
+			return
+		case "schedule.ics":
+			if len(parts) != 2 {
+				WriteError(w, http.StatusNotFound, fmt.Errorf("unknown schedule path"))
+				return
+			}
+			h.channelScheduleCalendar(channelID, w, r)
+			return
 		case "chat":
 			h.handleChatRoutes(channelID, parts[2:], w, r)
 			return
+		case "dashboard":
+			channel, ok := h.Store.GetChannel(channelID)
+			if !ok {
+				WriteError(w, http.StatusNotFound, fmt.Errorf("channel %s not found", channelID))
+				return
+			}
+			h.handleChannelDashboard(channel, parts[2:], w, r)
+			return
+		case "chatters":
+			channel, ok := h.Store.GetChannel(channelID)
+			if !ok {
+				WriteError(w, http.StatusNotFound, fmt.Errorf("channel %s not found", channelID))
+				return
+			}
+			h.handleChatters(channel, parts[2:], w, r)
+			return
+		case "analytics":
+			channel, ok := h.Store.GetChannel(channelID)
+			if !ok {
+				WriteError(w, http.StatusNotFound, fmt.Errorf("channel %s not found", channelID))
+				return
+			}
+			h.handleChannelAnalytics(channel, parts[2:], w, r)
+			return
 		case "monetization":
 			channel, ok := h.Store.GetChannel(channelID)
 			if !ok {
@@ -0,0 +1,267 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/chat"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+const (
+	chatRestrictionNone     = "none"
+	chatRestrictionTimedOut = "timed_out"
+	chatRestrictionBanned   = "banned"
+)
+
+// chatRestrictionStatusResponse describes the requesting user's own chat
+// restriction. It never names the moderator who issued it.
+type chatRestrictionStatusResponse struct {
+	ChannelID string              `json:"channelId"`
+	State     string              `json:"state"`
+	Reason    string              `json:"reason,omitempty"`
+	IssuedAt  *string             `json:"issuedAt,omitempty"`
+	ExpiresAt *string             `json:"expiresAt,omitempty"`
+	Appeal    *chatAppealResponse `json:"appeal,omitempty"`
+}
+
+type chatAppealRequest struct {
+	Message string `json:"message"`
+}
+
+type resolveChatAppealRequest struct {
+	Decision string `json:"decision"`
+	Note     string `json:"note,omitempty"`
+}
+
+type chatAppealResponse struct {
+	ID             string  `json:"id"`
+	ChannelID      string  `json:"channelId"`
+	UserID         string  `json:"userId"`
+	Restriction    string  `json:"restriction"`
+	Message        string  `json:"message"`
+	Status         string  `json:"status"`
+	ResolverID     string  `json:"resolverId,omitempty"`
+	ResolutionNote string  `json:"resolutionNote,omitempty"`
+	CreatedAt      string  `json:"createdAt"`
+	ResolvedAt     *string `json:"resolvedAt,omitempty"`
+}
+
+// newChatAppealResponse renders appeal. The resolver is only included for
+// moderators; appellants see the decision and note.
+func newChatAppealResponse(appeal models.ChatAppeal, includeResolver bool) chatAppealResponse {
+	resp := chatAppealResponse{
+		ID:             appeal.ID,
+		ChannelID:      appeal.ChannelID,
+		UserID:         appeal.UserID,
+		Restriction:    appeal.Restriction,
+		Message:        appeal.Message,
+		Status:         appeal.Status,
+		ResolutionNote: appeal.ResolutionNote,
+		CreatedAt:      appeal.CreatedAt.Format(time.RFC3339Nano),
+	}
+	if includeResolver {
+		resp.ResolverID = appeal.ResolverID
+	}
+	if appeal.ResolvedAt != nil {
+		resolved := appeal.ResolvedAt.Format(time.RFC3339Nano)
+		resp.ResolvedAt = &resolved
+	}
+	return resp
+}
+
+// chatAppealError maps storage errors from the chat appeal methods.
+func chatAppealError(err error) RequestError {
+	switch {
+	case errors.Is(err, storage.ErrChatAppealNotFound):
+		return RequestError{Status: http.StatusNotFound, Message: "appeal not found", Err: err}
+	case errors.Is(err, storage.ErrNotChatRestricted):
+		return RequestError{Status: http.StatusConflict, CodeVal: "not_restricted", Message: "you are not banned or timed out in this chat", Err: err}
+	case errors.Is(err, storage.ErrChatAppealOpen):
+		return RequestError{Status: http.StatusConflict, CodeVal: "appeal_open", Message: "you already have an open appeal in this chat", Err: err}
+	case errors.Is(err, storage.ErrChatAppealResolved):
+		return RequestError{Status: http.StatusConflict, CodeVal: "appeal_resolved", Message: "appeal already resolved", Err: err}
+	default:
+		return RequestError{Status: http.StatusBadRequest, Err: err}
+	}
+}
+
+// handleChatRestriction serves GET /api/channels/{id}/chat/restriction with
+// the caller's own ban or timeout and their latest appeal.
+func (h *Handler) handleChatRestriction(actor models.User, channel models.Channel, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	resp := chatRestrictionStatusResponse{ChannelID: channel.ID, State: chatRestrictionNone}
+	restrictions, err := h.Store.ListChatRestrictions(channel.ID)
+	if err != nil {
+		writeStorageError(w, http.StatusInternalServerError, err)
+		return
+	}
+	var current *models.ChatRestriction
+	for i := range restrictions {
+		if restrictions[i].TargetID != actor.ID {
+			continue
+		}
+		// A ban outlasts any timeout, so it is the one to report.
+		if current == nil || restrictions[i].Type == "ban" {
+			current = &restrictions[i]
+		}
+	}
+	if current != nil {
+		resp.State = chatRestrictionTimedOut
+		if current.Type == "ban" {
+			resp.State = chatRestrictionBanned
+		}
+		resp.Reason = current.Reason
+		issued := current.IssuedAt.Format(time.RFC3339Nano)
+		resp.IssuedAt = &issued
+		if current.ExpiresAt != nil {
+			expires := current.ExpiresAt.Format(time.RFC3339Nano)
+			resp.ExpiresAt = &expires
+		}
+	}
+	if appeal, ok := h.Store.LatestChatAppeal(channel.ID, actor.ID); ok {
+		appealResp := newChatAppealResponse(appeal, false)
+		resp.Appeal = &appealResp
+	}
+	WriteJSON(w, http.StatusOK, resp)
+}
+
+// handleChatAppeals serves /api/channels/{id}/chat/appeals. Restricted
+// viewers file an appeal (POST) and read it back (GET /{appealId});
+// moderators list the queue and accept or reject appeals
+// (POST /{appealId}/resolve).
+func (h *Handler) handleChatAppeals(actor models.User, channel models.Channel, remaining []string, w http.ResponseWriter, r *http.Request) {
+	if len(remaining) > 0 && strings.TrimSpace(remaining[0]) != "" {
+		appealID := strings.TrimSpace(remaining[0])
+		switch {
+		case len(remaining) == 1:
+			if r.Method != http.MethodGet {
+				WriteMethodNotAllowed(w, r, http.MethodGet)
+				return
+			}
+			appeal, err := h.Store.GetChatAppeal(channel.ID, appealID)
+			if err != nil {
+				WriteRequestError(w, chatAppealError(err))
+				return
+			}
+			moderator := h.canModerateChannel(actor, channel)
+			if appeal.UserID != actor.ID && !moderator {
+				WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
+				return
+			}
+			WriteJSON(w, http.StatusOK, newChatAppealResponse(appeal, moderator))
+		case len(remaining) == 2 && remaining[1] == "resolve":
+			if r.Method != http.MethodPost {
+				WriteMethodNotAllowed(w, r, http.MethodPost)
+				return
+			}
+			if !h.canModerateChannel(actor, channel) {
+				WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
+				return
+			}
+			h.resolveChatAppeal(actor, channel, appealID, w, r)
+		default:
+			WriteError(w, http.StatusNotFound, fmt.Errorf("unknown chat appeal path"))
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if !h.canModerateChannel(actor, channel) {
+			WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
+			return
+		}
+		status := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("status")))
+		appeals, err := h.Store.ListChatAppeals(channel.ID, status == "all")
+		if err != nil {
+			writeStorageError(w, http.StatusBadRequest, err)
+			return
+		}
+		response := make([]chatAppealResponse, 0, len(appeals))
+		for _, appeal := range appeals {
+			response = append(response, newChatAppealResponse(appeal, true))
+		}
+		WriteJSON(w, http.StatusOK, response)
+	case http.MethodPost:
+		var req chatAppealRequest
+		if !DecodeAndValidate(w, r, &req) {
+			return
+		}
+		appeal, err := h.Store.CreateChatAppeal(channel.ID, actor.ID, req.Message)
+		if err != nil {
+			WriteRequestError(w, chatAppealError(err))
+			return
+		}
+		WriteJSON(w, http.StatusCreated, newChatAppealResponse(appeal, false))
+	default:
+		WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodPost)
+	}
+}
+
+// resolveChatAppeal accepts or rejects an open appeal. Accepting lifts the
+// appellant's ban and timeout through the chat gateway, the same path
+// moderators use, before the decision is recorded.
+func (h *Handler) resolveChatAppeal(actor models.User, channel models.Channel, appealID string, w http.ResponseWriter, r *http.Request) {
+	var req resolveChatAppealRequest
+	if !DecodeAndValidate(w, r, &req) {
+		return
+	}
+	var accept bool
+	switch strings.ToLower(strings.TrimSpace(req.Decision)) {
+	case "accept":
+		accept = true
+	case "reject":
+	default:
+		WriteRequestError(w, ValidationError("decision must be accept or reject"))
+		return
+	}
+	appeal, err := h.Store.GetChatAppeal(channel.ID, appealID)
+	if err != nil {
+		WriteRequestError(w, chatAppealError(err))
+		return
+	}
+	if appeal.Status != storage.ChatAppealStatusOpen {
+		WriteRequestError(w, chatAppealError(storage.ErrChatAppealResolved))
+		return
+	}
+	if accept {
+		if h.ChatGateway == nil {
+			WriteRequestError(w, ServiceUnavailableError("chat gateway unavailable"))
+			return
+		}
+		var lifts []chat.ModerationAction
+		if appeal.Restriction == "ban" || h.Store.IsChatBanned(channel.ID, appeal.UserID) {
+			lifts = append(lifts, chat.ModerationActionUnban)
+		}
+		if expiry, ok := h.Store.ChatTimeout(channel.ID, appeal.UserID); appeal.Restriction == "timeout" || (ok && expiry.After(time.Now())) {
+			lifts = append(lifts, chat.ModerationActionRemoveTimeout)
+		}
+		for _, action := range lifts {
+			evt := chat.ModerationEvent{
+				Action:    action,
+				ChannelID: channel.ID,
+				ActorID:   actor.ID,
+				TargetID:  appeal.UserID,
+				Reason:    "appeal accepted",
+			}
+			if err := h.ChatGateway.ApplyModeration(r.Context(), actor, evt); err != nil {
+				writeStorageError(w, http.StatusBadRequest, err)
+				return
+			}
+		}
+	}
+	resolved, err := h.Store.ResolveChatAppeal(channel.ID, appealID, actor.ID, accept, req.Note)
+	if err != nil {
+		WriteRequestError(w, chatAppealError(err))
+		return
+	}
+	WriteJSON(w, http.StatusOK, newChatAppealResponse(resolved, true))
+}
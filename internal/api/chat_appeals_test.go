@@ -0,0 +1,211 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/chat"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+func TestChatRestrictionReportsOwnState(t *testing.T) {
+	handler, store := newTestHandler(t)
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Arena", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+
+	status := func() (chatRestrictionStatusResponse, string) {
+		t.Helper()
+		req := withUser(httptest.NewRequest(http.MethodGet, "/api/channels/"+channel.ID+"/chat/restriction", nil), viewer)
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp chatRestrictionStatusResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode restriction: %v", err)
+		}
+		return resp, rec.Body.String()
+	}
+	moderate := func(action chat.ModerationAction, reason string, expiresAt *time.Time) {
+		t.Helper()
+		if err := store.ApplyChatEvent(chat.Event{
+			Type:       chat.EventTypeModeration,
+			Moderation: &chat.ModerationEvent{Action: action, ChannelID: channel.ID, ActorID: owner.ID, TargetID: viewer.ID, Reason: reason, ExpiresAt: expiresAt},
+			OccurredAt: time.Now().UTC(),
+		}); err != nil {
+			t.Fatalf("ApplyChatEvent %s: %v", action, err)
+		}
+	}
+
+	if resp, _ := status(); resp.State != chatRestrictionNone || resp.IssuedAt != nil || resp.Appeal != nil {
+		t.Fatalf("expected no restriction, got %+v", resp)
+	}
+
+	expiry := time.Now().UTC().Add(10 * time.Minute).Truncate(time.Millisecond)
+	moderate(chat.ModerationActionTimeout, "slow down", &expiry)
+	resp, body := status()
+	if resp.State != chatRestrictionTimedOut || resp.Reason != "slow down" || resp.ExpiresAt == nil || resp.IssuedAt == nil {
+		t.Fatalf("expected a timeout, got %+v", resp)
+	}
+	if until, err := time.Parse(time.RFC3339Nano, *resp.ExpiresAt); err != nil || !until.Equal(expiry) {
+		t.Fatalf("expected the timeout to end at %v, got %s", expiry, *resp.ExpiresAt)
+	}
+	if strings.Contains(body, owner.ID) {
+		t.Fatalf("expected the moderator withheld, got %s", body)
+	}
+
+	moderate(chat.ModerationActionBan, "spam", nil)
+	resp, body = status()
+	if resp.State != chatRestrictionBanned || resp.Reason != "spam" || resp.ExpiresAt != nil {
+		t.Fatalf("expected the ban to take precedence, got %+v", resp)
+	}
+	if strings.Contains(body, owner.ID) {
+		t.Fatalf("expected the moderator withheld, got %s", body)
+	}
+
+	moderate(chat.ModerationActionUnban, "", nil)
+	moderate(chat.ModerationActionRemoveTimeout, "", nil)
+	if resp, _ := status(); resp.State != chatRestrictionNone {
+		t.Fatalf("expected the restriction lifted, got %+v", resp)
+	}
+}
+
+func TestChatAppealsAcceptLiftsBanThroughGateway(t *testing.T) {
+	handler, store := newTestHandler(t)
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	bystander, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Bystander", Email: "bystander@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Arena", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	queue := chat.NewMemoryQueue(8)
+	handler.ChatGateway = chat.NewGateway(chat.GatewayConfig{Queue: queue, Store: store})
+	ctx, cancel := context.WithCancel(context.Background())
+	started := make(chan struct{})
+	stopped := make(chan struct{})
+	go func() {
+		defer close(stopped)
+		storage.NewChatWorker(store, queue, nil).WithStartedChannel(started).Run(ctx)
+	}()
+	// Stop the worker before the temp dir is removed; it may still be
+	// persisting the last message.
+	defer func() {
+		cancel()
+		<-stopped
+	}()
+	<-started
+
+	send := func(method, path string, user models.User, body string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(method, "/api/channels/"+channel.ID+"/chat"+path, strings.NewReader(body)), user)
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		return rec
+	}
+
+	rec := send(http.MethodPost, "/appeals", bystander, `{"message":"why not"}`)
+	if rec.Code != http.StatusConflict || decodeAPIError(t, rec.Body.Bytes()).Error.Code != "not_restricted" {
+		t.Fatalf("expected 409 not_restricted, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = send(http.MethodPost, "/moderation", owner, `{"action":"ban","targetId":"`+viewer.ID+`","reason":"spam"}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("ban: %d %s", rec.Code, rec.Body.String())
+	}
+	waitFor(t, "ban persisted", func() bool { return store.IsChatBanned(channel.ID, viewer.ID) })
+	if _, err := handler.ChatGateway.CreateMessage(ctx, viewer, channel.ID, "hello?", ""); err == nil {
+		t.Fatal("expected a banned viewer's message to be refused")
+	}
+
+	rec = send(http.MethodPost, "/appeals", viewer, `{"message":"I will behave"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var appeal chatAppealResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &appeal); err != nil {
+		t.Fatalf("decode appeal: %v", err)
+	}
+	if appeal.Status != storage.ChatAppealStatusOpen || appeal.Restriction != "ban" {
+		t.Fatalf("expected an open ban appeal, got %+v", appeal)
+	}
+	rec = send(http.MethodPost, "/appeals", viewer, `{"message":"please"}`)
+	if rec.Code != http.StatusConflict || decodeAPIError(t, rec.Body.Bytes()).Error.Code != "appeal_open" {
+		t.Fatalf("expected 409 appeal_open, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := send(http.MethodGet, "/appeals", viewer, ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected the appellant kept out of the queue, got %d", rec.Code)
+	}
+	if rec := send(http.MethodGet, "/appeals/"+appeal.ID, bystander, ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected another viewer kept out of the appeal, got %d", rec.Code)
+	}
+	rec = send(http.MethodGet, "/appeals", owner, "")
+	var queued []chatAppealResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &queued); err != nil || rec.Code != http.StatusOK || len(queued) != 1 || queued[0].ID != appeal.ID {
+		t.Fatalf("expected the appeal queued, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = send(http.MethodPost, "/appeals/"+appeal.ID+"/resolve", owner, `{"decision":"accept","note":"Welcome back"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = send(http.MethodPost, "/appeals/"+appeal.ID+"/resolve", owner, `{"decision":"reject"}`)
+	if rec.Code != http.StatusConflict || decodeAPIError(t, rec.Body.Bytes()).Error.Code != "appeal_resolved" {
+		t.Fatalf("expected 409 appeal_resolved, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	waitFor(t, "unban persisted", func() bool { return !store.IsChatBanned(channel.ID, viewer.ID) })
+	if _, err := handler.ChatGateway.CreateMessage(ctx, viewer, channel.ID, "thanks", ""); err != nil {
+		t.Fatalf("expected the viewer to chat again, got %v", err)
+	}
+
+	rec = send(http.MethodGet, "/restriction", viewer, "")
+	var status chatRestrictionStatusResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("decode restriction: %v", err)
+	}
+	if status.State != chatRestrictionNone || status.Appeal == nil || status.Appeal.Status != storage.ChatAppealStatusAccepted || status.Appeal.ResolutionNote != "Welcome back" {
+		t.Fatalf("expected no restriction and the accepted appeal, got %+v", status)
+	}
+	if strings.Contains(rec.Body.String(), owner.ID) {
+		t.Fatalf("expected the resolving moderator withheld, got %s", rec.Body.String())
+	}
+}
+
+// waitFor polls condition until it holds or a deadline passes.
+func waitFor(t *testing.T, what string, condition func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(2 * time.Second)
+	for !condition() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -8,14 +8,17 @@ import (
 	"strings"
 	"time"
 
+	"bitriver-live/internal/authz"
 	"bitriver-live/internal/chat"
 	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
 )
 
 // Chat request/response DTOs.
 type createChatRequest struct {
-	UserID  string `json:"userId"`
-	Content string `json:"content"`
+	UserID          string `json:"userId"`
+	Content         string `json:"content"`
+	ClientMessageID string `json:"clientMessageId,omitempty"`
 }
 
 type chatModerationRequest struct {
@@ -41,12 +44,16 @@ type moderationFlagResponse struct {
 	ID           string                  `json:"id"`
 	ChannelID    string                  `json:"channelId"`
 	ChannelTitle string                  `json:"channelTitle,omitempty"`
+	SubjectType  string                  `json:"subjectType"`
+	SubjectID    string                  `json:"subjectId,omitempty"`
+	Category     string                  `json:"category"`
 	Reporter     *moderationUserResponse `json:"reporter,omitempty"`
 	Target       *moderationUserResponse `json:"target,omitempty"`
 	Reason       string                  `json:"reason,omitempty"`
 	Message      string                  `json:"message,omitempty"`
 	MessageID    string                  `json:"messageId,omitempty"`
 	EvidenceURL  string                  `json:"evidenceUrl,omitempty"`
+	Claimant     *models.ReportClaimant  `json:"claimant,omitempty"`
 	CreatedAt    string                  `json:"createdAt,omitempty"`
 	FlaggedAt    string                  `json:"flaggedAt,omitempty"`
 }
@@ -85,21 +92,28 @@ type chatReportRequest struct {
 
 type resolveModerationRequest struct {
 	Resolution string `json:"resolution"`
+	// Action optionally acts on the reported subject before the report is
+	// resolved: reportActionUnpublishRecording or reportActionStopStream.
+	Action string `json:"action,omitempty"`
 }
 
 type chatReportResponse struct {
-	ID          string  `json:"id"`
-	ChannelID   string  `json:"channelId"`
-	ReporterID  string  `json:"reporterId"`
-	TargetID    string  `json:"targetId"`
-	Reason      string  `json:"reason"`
-	Status      string  `json:"status"`
-	Resolution  string  `json:"resolution,omitempty"`
-	MessageID   string  `json:"messageId,omitempty"`
-	EvidenceURL string  `json:"evidenceUrl,omitempty"`
-	CreatedAt   string  `json:"createdAt"`
-	ResolvedAt  *string `json:"resolvedAt,omitempty"`
-	ResolverID  string  `json:"resolverId,omitempty"`
+	ID          string                 `json:"id"`
+	ChannelID   string                 `json:"channelId"`
+	ReporterID  string                 `json:"reporterId"`
+	TargetID    string                 `json:"targetId"`
+	SubjectType string                 `json:"subjectType"`
+	SubjectID   string                 `json:"subjectId,omitempty"`
+	Category    string                 `json:"category"`
+	Reason      string                 `json:"reason"`
+	Status      string                 `json:"status"`
+	Resolution  string                 `json:"resolution,omitempty"`
+	MessageID   string                 `json:"messageId,omitempty"`
+	EvidenceURL string                 `json:"evidenceUrl,omitempty"`
+	Claimant    *models.ReportClaimant `json:"claimant,omitempty"`
+	CreatedAt   string                 `json:"createdAt"`
+	ResolvedAt  *string                `json:"resolvedAt,omitempty"`
+	ResolverID  string                 `json:"resolverId,omitempty"`
 }
 
 type resolveChatReportRequest struct {
@@ -107,20 +121,46 @@ type resolveChatReportRequest struct {
 }
 
 type chatMessageResponse struct {
-	ID        string `json:"id"`
-	ChannelID string `json:"channelId"`
-	UserID    string `json:"userId"`
-	Content   string `json:"content"`
-	CreatedAt string `json:"createdAt"`
+	ID              string            `json:"id"`
+	ChannelID       string            `json:"channelId"`
+	UserID          string            `json:"userId"`
+	Content         string            `json:"content"`
+	URLs            []chat.MessageURL `json:"urls,omitempty"`
+	ClientMessageID string            `json:"clientMessageId,omitempty"`
+	Seq             int64             `json:"seq,omitempty"`
+	CreatedAt       string            `json:"createdAt"`
+	IsFirstMessage  bool              `json:"isFirstMessage,omitempty"`
+	Author          *chat.Author      `json:"author,omitempty"`
 }
 
 func newChatMessageResponse(message models.ChatMessage) chatMessageResponse {
 	return chatMessageResponse{
-		ID:        message.ID,
-		ChannelID: message.ChannelID,
-		UserID:    message.UserID,
-		Content:   message.Content,
-		CreatedAt: message.CreatedAt.Format(time.RFC3339Nano),
+		ID:              message.ID,
+		ChannelID:       message.ChannelID,
+		UserID:          message.UserID,
+		Content:         message.Content,
+		URLs:            chat.ExtractURLs(message.Content),
+		ClientMessageID: message.ClientMessageID,
+		Seq:             message.Seq,
+		CreatedAt:       message.CreatedAt.Format(time.RFC3339Nano),
+	}
+}
+
+// chatAuthors resolves the authors of messages in the channel with a single
+// store lookup, so history pages do not fetch users one by one.
+func (h *Handler) chatAuthors(channel models.Channel, messages []models.ChatMessage) (map[string]chat.Author, error) {
+	ids := make([]string, 0, len(messages))
+	for _, message := range messages {
+		ids = append(ids, message.UserID)
+	}
+	return chat.ResolveAuthors(h.Store, channel, ids)
+}
+
+// invalidateChatAuthor drops the user's cached chat author view after a
+// change to their name, avatar, roles, or subscriptions.
+func (h *Handler) invalidateChatAuthor(userID string) {
+	if h.ChatGateway != nil {
+		h.ChatGateway.InvalidateAuthor(userID)
 	}
 }
 
@@ -149,11 +189,15 @@ func newChatReportResponse(report models.ChatReport) chatReportResponse {
 		ChannelID:   report.ChannelID,
 		ReporterID:  report.ReporterID,
 		TargetID:    report.TargetID,
+		SubjectType: report.SubjectType,
+		SubjectID:   report.SubjectID,
+		Category:    report.Category,
 		Reason:      report.Reason,
 		Status:      report.Status,
 		Resolution:  report.Resolution,
 		MessageID:   report.MessageID,
 		EvidenceURL: report.EvidenceURL,
+		Claimant:    report.Claimant,
 		CreatedAt:   report.CreatedAt.Format(time.RFC3339Nano),
 		ResolverID:  report.ResolverID,
 	}
@@ -207,6 +251,35 @@ func (h *Handler) handleChatRoutes(channelID string, remaining []string, w http.
 			}
 			h.handleChatReports(actor, channel, remaining[1:], w, r)
 			return
+		case "restriction":
+			actor, ok := h.requireAuthenticatedUser(w, r)
+			if !ok {
+				return
+			}
+			if len(remaining) > 1 {
+				WriteError(w, http.StatusNotFound, fmt.Errorf("unknown chat path"))
+				return
+			}
+			h.handleChatRestriction(actor, channel, w, r)
+			return
+		case "appeals":
+			actor, ok := h.requireAuthenticatedUser(w, r)
+			if !ok {
+				return
+			}
+			h.handleChatAppeals(actor, channel, remaining[1:], w, r)
+			return
+		case "pin":
+			if len(remaining) > 1 {
+				WriteError(w, http.StatusNotFound, fmt.Errorf("unknown chat path"))
+				return
+			}
+			actor, ok := h.requireAuthenticatedUser(w, r)
+			if !ok {
+				return
+			}
+			h.handleChatPin(actor, channel, w, r)
+			return
 		default:
 			messageID := remaining[0]
 			if len(remaining) > 1 {
@@ -221,12 +294,29 @@ func (h *Handler) handleChatRoutes(channelID string, remaining []string, w http.
 			if !ok {
 				return
 			}
-			if channel.OwnerID != actor.ID && !actor.HasRole(roleAdmin) {
+			if !h.canModerateChannel(actor, channel) {
 				WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
 				return
 			}
-			if err := h.Store.DeleteChatMessage(channelID, messageID); err != nil {
-				WriteError(w, http.StatusBadRequest, err)
+			// With a gateway the deletion goes through the chat stream, so
+			// connected and reconnecting clients learn about it; the
+			// persistence worker removes the message shortly after.
+			if h.ChatGateway != nil {
+				evt := chat.ModerationEvent{
+					Action:    chat.ModerationActionDeleteMessage,
+					ChannelID: channelID,
+					ActorID:   actor.ID,
+					MessageID: messageID,
+				}
+				if err := h.ChatGateway.ApplyModeration(r.Context(), actor, evt); err != nil {
+					writeStorageError(w, http.StatusBadRequest, err)
+					return
+				}
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			if err := h.Store.DeleteChatMessage(channelID, messageID, actor.ID); err != nil {
+				writeStorageError(w, http.StatusBadRequest, err)
 				return
 			}
 			w.WriteHeader(http.StatusNoContent)
@@ -248,51 +338,83 @@ func (h *Handler) handleChatRoutes(channelID string, remaining []string, w http.
 		}
 		messages, err := h.Store.ListChatMessages(channelID, limit)
 		if err != nil {
-			WriteError(w, http.StatusBadRequest, err)
+			writeStorageError(w, http.StatusBadRequest, err)
+			return
+		}
+		authors, err := h.chatAuthors(channel, messages)
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, fmt.Errorf("resolve chat authors: %w", err))
 			return
 		}
 		response := make([]chatMessageResponse, 0, len(messages))
 		for _, message := range messages {
-			response = append(response, newChatMessageResponse(message))
+			resp := newChatMessageResponse(message)
+			author := authors[message.UserID]
+			resp.Author = &author
+			response = append(response, resp)
 		}
-		WriteJSON(w, http.StatusOK, response)
+		// Versioned requests get the transcript with the chat box setup;
+		// unversioned clients keep the bare array.
+		if !requestAPIVersion(r).Versioned() {
+			h.markLegacyShape(w, r)
+			WriteJSON(w, http.StatusOK, response)
+			return
+		}
+		history := chatHistoryResponse{Messages: response, WelcomeMessage: channel.ChatWelcomeMessage}
+		if pin, ok := h.Store.ChatPin(channelID); ok {
+			pinResp := newChatPinResponse(pin)
+			history.Pin = &pinResp
+		}
+		WriteJSON(w, http.StatusOK, history)
 	case http.MethodPost:
 		actor, ok := h.requireAuthenticatedUser(w, r)
 		if !ok {
 			return
 		}
+		if !h.requireScope(w, r, storage.APITokenScopeChat) {
+			return
+		}
 		var req createChatRequest
 		if !DecodeAndValidate(w, r, &req) {
 			return
 		}
-		if req.UserID != actor.ID && !actor.HasRole(roleAdmin) {
+		if req.UserID != actor.ID && !authz.Has(actor, authz.UsersManage) {
 			WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
 			return
 		}
+		clientMessageID, err := chat.NormalizeClientMessageID(req.ClientMessageID)
+		if err != nil {
+			WriteRequestError(w, ValidationError(err.Error()))
+			return
+		}
 		if h.ChatGateway != nil {
 			author, ok := h.Store.GetUser(req.UserID)
 			if !ok {
 				WriteRequestError(w, ValidationError(fmt.Sprintf("user %s not found", req.UserID)))
 				return
 			}
-			messageEvt, err := h.ChatGateway.CreateMessage(r.Context(), author, channelID, req.Content)
+			messageEvt, err := h.ChatGateway.CreateMessage(r.Context(), author, channelID, req.Content, clientMessageID)
 			if err != nil {
-				WriteError(w, http.StatusBadRequest, err)
+				writeChatMessageError(w, err)
 				return
 			}
 			chatMessage := models.ChatMessage{
-				ID:        messageEvt.ID,
-				ChannelID: messageEvt.ChannelID,
-				UserID:    messageEvt.UserID,
-				Content:   messageEvt.Content,
-				CreatedAt: messageEvt.CreatedAt,
+				ID:              messageEvt.ID,
+				ChannelID:       messageEvt.ChannelID,
+				UserID:          messageEvt.UserID,
+				Content:         messageEvt.Content,
+				ClientMessageID: messageEvt.ClientMessageID,
+				CreatedAt:       messageEvt.CreatedAt,
 			}
-			WriteJSON(w, http.StatusCreated, newChatMessageResponse(chatMessage))
+			resp := newChatMessageResponse(chatMessage)
+			resp.Author = messageEvt.Author
+			resp.IsFirstMessage = messageEvt.IsFirstMessage
+			WriteJSON(w, http.StatusCreated, resp)
 			return
 		}
-		message, err := h.Store.CreateChatMessage(channelID, req.UserID, req.Content)
+		message, err := h.Store.CreateChatMessage(channelID, req.UserID, req.Content, clientMessageID)
 		if err != nil {
-			WriteError(w, http.StatusBadRequest, err)
+			writeChatMessageError(w, err)
 			return
 		}
 		WriteJSON(w, http.StatusCreated, newChatMessageResponse(message))
@@ -301,6 +423,17 @@ func (h *Handler) handleChatRoutes(channelID string, remaining []string, w http.
 	}
 }
 
+// writeChatMessageError reports why a chat message was refused. Refusals
+// with a chat.ErrorCode, such as subscriber-only chat, are forbidden with
+// that code; anything else is a bad request.
+func writeChatMessageError(w http.ResponseWriter, err error) {
+	if code := chat.ErrorCode(err); code != "" {
+		WriteRequestError(w, RequestError{Status: http.StatusForbidden, CodeVal: code, Message: err.Error(), Err: err})
+		return
+	}
+	writeStorageError(w, http.StatusBadRequest, err)
+}
+
 func (h *Handler) handleChatModeration(actor models.User, channel models.Channel, remaining []string, w http.ResponseWriter, r *http.Request) {
 	if h.ChatGateway == nil {
 		WriteRequestError(w, ServiceUnavailableError("chat gateway unavailable"))
@@ -313,11 +446,15 @@ func (h *Handler) handleChatModeration(actor models.User, channel models.Channel
 				WriteMethodNotAllowed(w, r, http.MethodGet)
 				return
 			}
-			if channel.OwnerID != actor.ID && !actor.HasRole(roleAdmin) {
+			if !h.canModerateChannel(actor, channel) {
 				WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
 				return
 			}
-			restrictions := h.Store.ListChatRestrictions(channel.ID)
+			restrictions, err := h.Store.ListChatRestrictions(channel.ID)
+			if err != nil {
+				writeStorageError(w, http.StatusInternalServerError, err)
+				return
+			}
 			response := make([]chatRestrictionResponse, 0, len(restrictions))
 			for _, restriction := range restrictions {
 				response = append(response, newChatRestrictionResponse(restriction))
@@ -337,7 +474,7 @@ func (h *Handler) handleChatModeration(actor models.User, channel models.Channel
 		WriteMethodNotAllowed(w, r, http.MethodPost)
 		return
 	}
-	if channel.OwnerID != actor.ID && !actor.HasRole(roleAdmin) {
+	if !h.canModerateChannel(actor, channel) {
 		WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
 		return
 	}
@@ -381,7 +518,7 @@ func (h *Handler) handleChatModeration(actor models.User, channel models.Channel
 	}
 
 	if err := h.ChatGateway.ApplyModeration(r.Context(), actor, evt); err != nil {
-		WriteError(w, http.StatusBadRequest, err)
+		writeStorageError(w, http.StatusBadRequest, err)
 		return
 	}
 	var expires *string
@@ -405,7 +542,7 @@ func (h *Handler) handleChatReports(actor models.User, channel models.Channel, r
 				WriteMethodNotAllowed(w, r, http.MethodPost)
 				return
 			}
-			if channel.OwnerID != actor.ID && !actor.HasRole(roleAdmin) {
+			if !authz.CanResolveChannelReports(actor, channel) {
 				WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
 				return
 			}
@@ -415,7 +552,7 @@ func (h *Handler) handleChatReports(actor models.User, channel models.Channel, r
 			}
 			report, err := h.Store.ResolveChatReport(reportID, actor.ID, req.Resolution)
 			if err != nil {
-				WriteError(w, http.StatusBadRequest, err)
+				writeStorageError(w, http.StatusBadRequest, err)
 				return
 			}
 			WriteJSON(w, http.StatusOK, newChatReportResponse(report))
@@ -427,18 +564,19 @@ func (h *Handler) handleChatReports(actor models.User, channel models.Channel, r
 
 	switch r.Method {
 	case http.MethodGet:
-		if channel.OwnerID != actor.ID && !actor.HasRole(roleAdmin) {
+		if !authz.CanResolveChannelReports(actor, channel) {
 			WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
 			return
 		}
-		includeResolved := false
+		filter := reportFilterFromQuery(r)
+		filter.ChannelID = channel.ID
 		status := strings.ToLower(strings.TrimSpace(r.URL.Query().Get("status")))
 		if status == "all" || status == "resolved" {
-			includeResolved = true
+			filter.IncludeResolved = true
 		}
-		reports, err := h.Store.ListChatReports(channel.ID, includeResolved)
+		reports, err := h.Store.ListReports(filter)
 		if err != nil {
-			WriteError(w, http.StatusBadRequest, err)
+			writeStorageError(w, http.StatusBadRequest, err)
 			return
 		}
 		response := make([]chatReportResponse, 0, len(reports))
@@ -475,7 +613,7 @@ func (h *Handler) handleChatReports(actor models.User, channel models.Channel, r
 			}
 			evt, err := h.ChatGateway.SubmitReport(r.Context(), reporter, channel.ID, targetID, reason, messageID, evidence)
 			if err != nil {
-				WriteError(w, http.StatusBadRequest, err)
+				writeStorageError(w, http.StatusBadRequest, err)
 				return
 			}
 			report := models.ChatReport{
@@ -483,6 +621,9 @@ func (h *Handler) handleChatReports(actor models.User, channel models.Channel, r
 				ChannelID:   evt.ChannelID,
 				ReporterID:  evt.ReporterID,
 				TargetID:    evt.TargetID,
+				SubjectType: models.ReportSubjectChatMessage,
+				SubjectID:   evt.MessageID,
+				Category:    models.ReportCategoryOther,
 				Reason:      evt.Reason,
 				MessageID:   evt.MessageID,
 				EvidenceURL: evt.EvidenceURL,
@@ -494,7 +635,7 @@ func (h *Handler) handleChatReports(actor models.User, channel models.Channel, r
 		}
 		report, err := h.Store.CreateChatReport(channel.ID, actor.ID, targetID, reason, messageID, evidence)
 		if err != nil {
-			WriteError(w, http.StatusBadRequest, err)
+			writeStorageError(w, http.StatusBadRequest, err)
 			return
 		}
 		WriteJSON(w, http.StatusAccepted, newChatReportResponse(report))
@@ -509,13 +650,18 @@ func (h *Handler) ModerationQueue(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	if _, ok := h.requireRole(w, r, roleAdmin); !ok {
+	if _, ok := h.requirePermission(w, r, authz.ReportsResolveAny); !ok {
 		return
 	}
 
-	payload, err := h.moderationQueuePayload()
+	filter := reportFilterFromQuery(r)
+	if reqErr, ok := validateReportFilter(filter); !ok {
+		WriteRequestError(w, reqErr)
+		return
+	}
+	payload, err := h.moderationQueuePayload(filter)
 	if err != nil {
-		WriteError(w, http.StatusInternalServerError, err)
+		writeStorageError(w, http.StatusInternalServerError, err)
 		return
 	}
 	WriteJSON(w, http.StatusOK, payload)
@@ -533,7 +679,7 @@ func (h *Handler) ModerationQueueByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	actor, ok := h.requireRole(w, r, roleAdmin)
+	actor, ok := h.requirePermission(w, r, authz.ReportsResolveAny)
 	if !ok {
 		return
 	}
@@ -548,16 +694,38 @@ func (h *Handler) ModerationQueueByID(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	if action := strings.TrimSpace(req.Action); action != "" {
+		report, ok := h.Store.GetReport(flagID)
+		if !ok {
+			WriteError(w, http.StatusNotFound, fmt.Errorf("report %s not found", flagID))
+			return
+		}
+		if reqErr := h.applyReportAction(r, actor, report, action); reqErr != nil {
+			WriteRequestError(w, reqErr)
+			return
+		}
+	}
+
 	report, err := h.Store.ResolveChatReport(flagID, actor.ID, resolution)
 	if err != nil {
-		WriteError(w, http.StatusBadRequest, err)
+		writeStorageError(w, http.StatusBadRequest, err)
 		return
 	}
 	WriteJSON(w, http.StatusOK, newChatReportResponse(report))
 }
 
-func (h *Handler) moderationQueuePayload() (moderationQueueResponse, error) {
-	channels := h.Store.ListChannels("", "")
+// moderationQueuePayload lists open reports across every channel, and the
+// most recently resolved ones, narrowed to filter's subject type and
+// category.
+func (h *Handler) moderationQueuePayload(filter storage.ReportFilter) (moderationQueueResponse, error) {
+	channels, err := h.Store.ListChannels("", "")
+	if err != nil {
+		return moderationQueueResponse{}, err
+	}
+	channelsByID := make(map[string]models.Channel, len(channels))
+	for _, channel := range channels {
+		channelsByID[channel.ID] = channel
+	}
 	type flaggedItem struct {
 		payload moderationFlagResponse
 		created time.Time
@@ -568,60 +736,67 @@ func (h *Handler) moderationQueuePayload() (moderationQueueResponse, error) {
 	}
 	flags := make([]flaggedItem, 0)
 	actions := make([]actionItem, 0)
-	for _, channel := range channels {
-		reports, err := h.Store.ListChatReports(channel.ID, true)
-		if err != nil {
-			return moderationQueueResponse{}, err
+	filter.IncludeResolved = true
+	reports, err := h.Store.ListReports(filter)
+	if err != nil {
+		return moderationQueueResponse{}, err
+	}
+	for _, report := range reports {
+		channel, ok := channelsByID[report.ChannelID]
+		if !ok {
+			continue
 		}
-		for _, report := range reports {
-			reporter, hasReporter := h.Store.GetUser(report.ReporterID)
-			target, hasTarget := h.Store.GetUser(report.TargetID)
-			createdAt := report.CreatedAt
-			flag := moderationFlagResponse{
+		reporter, hasReporter := h.Store.GetUser(report.ReporterID)
+		target, hasTarget := h.Store.GetUser(report.TargetID)
+		createdAt := report.CreatedAt
+		flag := moderationFlagResponse{
+			ID:           report.ID,
+			ChannelID:    report.ChannelID,
+			ChannelTitle: channel.Title,
+			SubjectType:  report.SubjectType,
+			SubjectID:    report.SubjectID,
+			Category:     report.Category,
+			Reason:       report.Reason,
+			MessageID:    report.MessageID,
+			EvidenceURL:  report.EvidenceURL,
+			Claimant:     report.Claimant,
+			CreatedAt:    createdAt.Format(time.RFC3339Nano),
+			FlaggedAt:    createdAt.Format(time.RFC3339Nano),
+		}
+		if hasReporter {
+			reporterResp := newModerationUser(reporter)
+			flag.Reporter = &reporterResp
+		}
+		if hasTarget {
+			targetResp := newModerationUser(target)
+			flag.Target = &targetResp
+		}
+		if strings.EqualFold(report.Status, "open") {
+			flags = append(flags, flaggedItem{payload: flag, created: createdAt})
+			continue
+		}
+		if strings.EqualFold(report.Status, "resolved") {
+			resolvedAt := createdAt
+			if report.ResolvedAt != nil {
+				resolvedAt = report.ResolvedAt.UTC()
+			}
+			moderatorResp := (*moderationUserResponse)(nil)
+			if resolverID := strings.TrimSpace(report.ResolverID); resolverID != "" {
+				if moderator, exists := h.Store.GetUser(resolverID); exists {
+					value := newModerationUser(moderator)
+					moderatorResp = &value
+				}
+			}
+			action := moderationActionResponse{
 				ID:           report.ID,
 				ChannelID:    report.ChannelID,
 				ChannelTitle: channel.Title,
-				Reason:       report.Reason,
-				MessageID:    report.MessageID,
-				EvidenceURL:  report.EvidenceURL,
-				CreatedAt:    createdAt.Format(time.RFC3339Nano),
-				FlaggedAt:    createdAt.Format(time.RFC3339Nano),
-			}
-			if hasReporter {
-				reporterResp := newModerationUser(reporter)
-				flag.Reporter = &reporterResp
-			}
-			if hasTarget {
-				targetResp := newModerationUser(target)
-				flag.Target = &targetResp
-			}
-			if strings.EqualFold(report.Status, "open") {
-				flags = append(flags, flaggedItem{payload: flag, created: createdAt})
-				continue
-			}
-			if strings.EqualFold(report.Status, "resolved") {
-				resolvedAt := createdAt
-				if report.ResolvedAt != nil {
-					resolvedAt = report.ResolvedAt.UTC()
-				}
-				moderatorResp := (*moderationUserResponse)(nil)
-				if resolverID := strings.TrimSpace(report.ResolverID); resolverID != "" {
-					if moderator, exists := h.Store.GetUser(resolverID); exists {
-						value := newModerationUser(moderator)
-						moderatorResp = &value
-					}
-				}
-				action := moderationActionResponse{
-					ID:           report.ID,
-					ChannelID:    report.ChannelID,
-					ChannelTitle: channel.Title,
-					Action:       strings.TrimSpace(report.Resolution),
-					TargetID:     report.TargetID,
-					Moderator:    moderatorResp,
-					CreatedAt:    resolvedAt.Format(time.RFC3339Nano),
-				}
-				actions = append(actions, actionItem{payload: action, created: resolvedAt})
+				Action:       strings.TrimSpace(report.Resolution),
+				TargetID:     report.TargetID,
+				Moderator:    moderatorResp,
+				CreatedAt:    resolvedAt.Format(time.RFC3339Nano),
 			}
+			actions = append(actions, actionItem{payload: action, created: resolvedAt})
 		}
 	}
 	sort.Slice(flags, func(i, j int) bool {
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"bitriver-live/internal/chat"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+type chatPinRequest struct {
+	MessageID string `json:"messageId,omitempty"`
+	Content   string `json:"content,omitempty"`
+}
+
+type chatPinResponse struct {
+	ChannelID string `json:"channelId"`
+	MessageID string `json:"messageId,omitempty"`
+	AuthorID  string `json:"authorId,omitempty"`
+	Content   string `json:"content"`
+	PinnedBy  string `json:"pinnedBy,omitempty"`
+	PinnedAt  string `json:"pinnedAt"`
+}
+
+func newChatPinResponse(pin models.ChatPin) chatPinResponse {
+	return chatPinResponse{
+		ChannelID: pin.ChannelID,
+		MessageID: pin.MessageID,
+		AuthorID:  pin.AuthorID,
+		Content:   pin.Content,
+		PinnedBy:  pin.PinnedBy,
+		PinnedAt:  pin.PinnedAt.Format(time.RFC3339Nano),
+	}
+}
+
+// chatHistoryResponse is the versioned shape of GET /api/channels/{id}/chat.
+// Besides the transcript it carries what a client needs to set up the chat
+// box: the pinned message and the channel's welcome message.
+type chatHistoryResponse struct {
+	Messages       []chatMessageResponse `json:"messages"`
+	Pin            *chatPinResponse      `json:"pin,omitempty"`
+	WelcomeMessage string                `json:"welcomeMessage,omitempty"`
+}
+
+// handleChatPin serves /api/channels/{id}/chat/pin. The channel owner and
+// moderators pin an existing message or free text with POST, replacing the
+// current pin, and remove it with DELETE. Both are announced to the chat
+// room.
+func (h *Handler) handleChatPin(actor models.User, channel models.Channel, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodDelete {
+		WriteMethodNotAllowed(w, r, http.MethodPost, http.MethodDelete)
+		return
+	}
+	if !h.canModerateChannel(actor, channel) {
+		WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
+		return
+	}
+
+	if r.Method == http.MethodDelete {
+		pin, err := h.Store.UnpinChatMessage(channel.ID)
+		if err != nil {
+			if errors.Is(err, storage.ErrChatPinNotFound) {
+				WriteRequestError(w, RequestError{Status: http.StatusNotFound, CodeVal: "no_pin", Message: "no message is pinned", Err: err})
+				return
+			}
+			writeStorageError(w, http.StatusBadRequest, err)
+			return
+		}
+		h.announceChatPin(r, chat.PinActionUnpin, pin)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	var req chatPinRequest
+	if !DecodeAndValidate(w, r, &req) {
+		return
+	}
+	pin, err := h.Store.PinChatMessage(channel.ID, storage.ChatPinParams{
+		ActorID:   actor.ID,
+		MessageID: req.MessageID,
+		Content:   req.Content,
+	})
+	if err != nil {
+		writeStorageError(w, http.StatusBadRequest, err)
+		return
+	}
+	h.announceChatPin(r, chat.PinActionPin, pin)
+	WriteJSON(w, http.StatusOK, newChatPinResponse(pin))
+}
+
+// announceChatPin tells the chat room about a pin change. The pin is already
+// stored, so a failed announcement is only logged.
+func (h *Handler) announceChatPin(r *http.Request, action chat.PinAction, pin models.ChatPin) {
+	if h.ChatGateway == nil {
+		return
+	}
+	if err := h.ChatGateway.AnnouncePin(r.Context(), chat.PinEvent{
+		Action:    action,
+		ChannelID: pin.ChannelID,
+		MessageID: pin.MessageID,
+		AuthorID:  pin.AuthorID,
+		Content:   pin.Content,
+		PinnedBy:  pin.PinnedBy,
+		PinnedAt:  pin.PinnedAt,
+	}); err != nil {
+		h.logger().Warn("announce chat pin", "channel_id", pin.ChannelID, "action", string(action), "error", err)
+	}
+}
@@ -0,0 +1,124 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/chat"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+func TestChatPinsAnnounceAndReachTheHistory(t *testing.T) {
+	handler, store := newTestHandler(t)
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser owner: %v", err)
+	}
+	viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser viewer: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Arena", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	welcome := "Be kind in chat"
+	if _, err := store.UpdateChannel(channel.ID, storage.ChannelUpdate{ChatWelcomeMessage: &welcome}); err != nil {
+		t.Fatalf("UpdateChannel: %v", err)
+	}
+	message, err := store.CreateChatMessage(channel.ID, viewer.ID, "gg everyone", "")
+	if err != nil {
+		t.Fatalf("CreateChatMessage: %v", err)
+	}
+
+	queue := chat.NewMemoryQueue(4)
+	events := queue.Subscribe()
+	defer events.Close()
+	handler.ChatGateway = chat.NewGateway(chat.GatewayConfig{Queue: queue, Store: store})
+	nextPin := func() chat.PinEvent {
+		t.Helper()
+		select {
+		case evt := <-events.Events():
+			if evt.Type != chat.EventTypePin || evt.Pin == nil {
+				t.Fatalf("expected a pin event, got %+v", evt)
+			}
+			return *evt.Pin
+		case <-time.After(time.Second):
+			t.Fatal("timeout waiting for pin event")
+		}
+		return chat.PinEvent{}
+	}
+
+	serve := func(method, path string, version APIVersion, user models.User, body string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(method, path, strings.NewReader(body)), user)
+		req = req.WithContext(ContextWithAPIVersion(req.Context(), version))
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		return rec
+	}
+	pinPath := "/api/channels/" + channel.ID + "/chat/pin"
+	pin := func(method string, user models.User, body string) *httptest.ResponseRecorder {
+		return serve(method, pinPath, APIVersionLegacy, user, body)
+	}
+	history := func(version APIVersion) *httptest.ResponseRecorder {
+		return serve(http.MethodGet, "/api/channels/"+channel.ID+"/chat", version, viewer, "")
+	}
+
+	if rec := pin(http.MethodPost, viewer, `{"content":"mine now"}`); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for viewers, got %d", rec.Code)
+	}
+	rec := pin(http.MethodDelete, owner, "")
+	if rec.Code != http.StatusNotFound || decodeAPIError(t, rec.Body.Bytes()).Error.Code != "no_pin" {
+		t.Fatalf("expected 404 no_pin, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = pin(http.MethodPost, owner, `{"messageId":"`+message.ID+`"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 pinning a message, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if evt := nextPin(); evt.Action != chat.PinActionPin || evt.MessageID != message.ID || evt.AuthorID != viewer.ID || evt.Content != "gg everyone" {
+		t.Fatalf("unexpected pin event: %+v", evt)
+	}
+
+	rec = pin(http.MethodPost, owner, `{"content":"Giveaway at 8pm"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 pinning text, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if evt := nextPin(); evt.Action != chat.PinActionPin || evt.MessageID != "" || evt.Content != "Giveaway at 8pm" || evt.PinnedBy != owner.ID {
+		t.Fatalf("unexpected pin event: %+v", evt)
+	}
+
+	rec = history(APIVersionLegacy)
+	var legacy []chatMessageResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &legacy); err != nil || len(legacy) != 1 {
+		t.Fatalf("expected the legacy history to stay a bare array, got %s (%v)", rec.Body.String(), err)
+	}
+	if rec.Header().Get("Deprecation") != "true" {
+		t.Fatal("expected the legacy history to be marked deprecated")
+	}
+	rec = history(APIVersion1)
+	var bootstrap chatHistoryResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &bootstrap); err != nil {
+		t.Fatalf("decode history: %v", err)
+	}
+	if len(bootstrap.Messages) != 1 || bootstrap.WelcomeMessage != welcome || bootstrap.Pin == nil || bootstrap.Pin.Content != "Giveaway at 8pm" {
+		t.Fatalf("expected the history with the pin and welcome message, got %s", rec.Body.String())
+	}
+
+	if rec := pin(http.MethodDelete, owner, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204 unpinning, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if evt := nextPin(); evt.Action != chat.PinActionUnpin || evt.Content != "Giveaway at 8pm" {
+		t.Fatalf("unexpected unpin event: %+v", evt)
+	}
+	rec = history(APIVersion1)
+	bootstrap = chatHistoryResponse{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &bootstrap); err != nil || bootstrap.Pin != nil {
+		t.Fatalf("expected no pin after unpinning, got %s (%v)", rec.Body.String(), err)
+	}
+}
@@ -0,0 +1,78 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+type chatterStatsWindowResponse struct {
+	Since             string `json:"since"`
+	UniqueChatters    int    `json:"uniqueChatters"`
+	FirstTimeChatters int    `json:"firstTimeChatters"`
+}
+
+type chatterSessionStatsResponse struct {
+	SessionID string `json:"sessionId"`
+	chatterStatsWindowResponse
+}
+
+type chatterStatsResponse struct {
+	ChannelID string                       `json:"channelId"`
+	Today     chatterStatsWindowResponse   `json:"today"`
+	Session   *chatterSessionStatsResponse `json:"session,omitempty"`
+}
+
+func newChatterStatsWindowResponse(since time.Time, stats storage.ChatterStats) chatterStatsWindowResponse {
+	return chatterStatsWindowResponse{
+		Since:             since.Format(time.RFC3339Nano),
+		UniqueChatters:    stats.UniqueChatters,
+		FirstTimeChatters: stats.FirstTimeChatters,
+	}
+}
+
+// handleChatters serves /api/channels/{id}/chatters/stats. The channel owner
+// and moderators see how many distinct users chatted since midnight UTC and
+// during the live session, and how many of them chatted for the first time.
+func (h *Handler) handleChatters(channel models.Channel, remaining []string, w http.ResponseWriter, r *http.Request) {
+	if len(remaining) != 1 || remaining[0] != "stats" {
+		WriteError(w, http.StatusNotFound, fmt.Errorf("unknown chatters path"))
+		return
+	}
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	actor, ok := h.requireAuthenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	if !h.canModerateChannel(actor, channel) {
+		WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
+		return
+	}
+
+	today := h.now().UTC().Truncate(24 * time.Hour)
+	stats, err := h.Store.ChatterStats(channel.ID, today)
+	if err != nil {
+		writeStorageError(w, http.StatusInternalServerError, err)
+		return
+	}
+	resp := chatterStatsResponse{ChannelID: channel.ID, Today: newChatterStatsWindowResponse(today, stats)}
+	if session, live := h.Store.CurrentStreamSession(channel.ID); live {
+		sessionStats, err := h.Store.ChatterStats(channel.ID, session.StartedAt)
+		if err != nil {
+			writeStorageError(w, http.StatusInternalServerError, err)
+			return
+		}
+		resp.Session = &chatterSessionStatsResponse{
+			SessionID:                  session.ID,
+			chatterStatsWindowResponse: newChatterStatsWindowResponse(session.StartedAt.UTC(), sessionStats),
+		}
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	WriteJSON(w, http.StatusOK, resp)
+}
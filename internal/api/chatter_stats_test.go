@@ -0,0 +1,88 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/chat"
+	"bitriver-live/internal/storage"
+)
+
+func TestChatterStats(t *testing.T) {
+	handler, store := newTestHandler(t)
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser owner: %v", err)
+	}
+	regular, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Regular", Email: "regular@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser regular: %v", err)
+	}
+	newcomer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Newcomer", Email: "newcomer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser newcomer: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Chatty", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+
+	now := time.Now().UTC()
+	handler.Now = func() time.Time { return now }
+	midnight := now.Truncate(24 * time.Hour)
+	say := func(id, userID string, at time.Time) {
+		t.Helper()
+		evt := chat.Event{Type: chat.EventTypeMessage, Message: &chat.MessageEvent{ID: id, ChannelID: channel.ID, UserID: userID, Content: id, CreatedAt: at}, OccurredAt: at}
+		if err := store.ApplyChatEvent(evt); err != nil {
+			t.Fatalf("ApplyChatEvent %s: %v", id, err)
+		}
+	}
+	say("last-week", regular.ID, midnight.Add(-7*24*time.Hour))
+	say("early", regular.ID, midnight)
+
+	stats := func() (int, chatterStatsResponse) {
+		t.Helper()
+		req := withUser(httptest.NewRequest(http.MethodGet, "/api/channels/"+channel.ID+"/chatters/stats", nil), owner)
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		var resp chatterStatsResponse
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+				t.Fatalf("decode stats: %v", err)
+			}
+		}
+		return rec.Code, resp
+	}
+
+	code, resp := stats()
+	if code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if resp.Today.UniqueChatters != 1 || resp.Today.FirstTimeChatters != 0 || resp.Today.Since != midnight.Format(time.RFC3339Nano) {
+		t.Fatalf("expected one returning chatter since midnight, got %+v", resp.Today)
+	}
+	if resp.Session != nil {
+		t.Fatalf("expected no session stats while offline, got %+v", resp.Session)
+	}
+
+	session, err := store.StartStream(channel.ID, []string{"720p"})
+	if err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	say("hello-again", regular.ID, session.StartedAt.Add(time.Second))
+	say("first", newcomer.ID, session.StartedAt.Add(2*time.Second))
+	say("second", newcomer.ID, session.StartedAt.Add(3*time.Second))
+
+	if code, resp = stats(); code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", code)
+	}
+	if resp.Today.UniqueChatters != 2 || resp.Today.FirstTimeChatters != 1 {
+		t.Fatalf("expected two chatters today, one of them new, got %+v", resp.Today)
+	}
+	if resp.Session == nil || resp.Session.SessionID != session.ID || resp.Session.UniqueChatters != 2 || resp.Session.FirstTimeChatters != 1 {
+		t.Fatalf("expected session stats with one first-timer, got %+v", resp.Session)
+	}
+}
@@ -0,0 +1,81 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"bitriver-live/internal/authz"
+	"bitriver-live/internal/observability/health"
+)
+
+type backgroundComponentResponse struct {
+	Name              string  `json:"name"`
+	Status            string  `json:"status"`
+	LastHeartbeat     *string `json:"lastHeartbeat,omitempty"`
+	LastError         string  `json:"lastError,omitempty"`
+	LastErrorAt       *string `json:"lastErrorAt,omitempty"`
+	StaleAfterSeconds int     `json:"staleAfterSeconds,omitempty"`
+}
+
+type componentHealthResponse struct {
+	Status      string                        `json:"status"`
+	Components  []backgroundComponentResponse `json:"components"`
+	GeneratedAt string                        `json:"generatedAt"`
+}
+
+func newBackgroundComponentResponse(snapshot health.ComponentSnapshot) backgroundComponentResponse {
+	resp := backgroundComponentResponse{
+		Name:      snapshot.Name,
+		Status:    snapshot.Status,
+		LastError: snapshot.LastError,
+	}
+	if !snapshot.LastHeartbeat.IsZero() {
+		value := snapshot.LastHeartbeat.UTC().Format(time.RFC3339Nano)
+		resp.LastHeartbeat = &value
+	}
+	if !snapshot.LastErrorAt.IsZero() {
+		value := snapshot.LastErrorAt.UTC().Format(time.RFC3339Nano)
+		resp.LastErrorAt = &value
+	}
+	if snapshot.StaleAfter > 0 {
+		resp.StaleAfterSeconds = int(snapshot.StaleAfter / time.Second)
+	}
+	return resp
+}
+
+// backgroundComponents reports every registered background component and
+// whether all of them are healthy.
+func (h *Handler) backgroundComponents() ([]backgroundComponentResponse, bool) {
+	snapshots := h.ComponentHealth.Snapshot()
+	components := make([]backgroundComponentResponse, 0, len(snapshots))
+	healthy := true
+	for _, snapshot := range snapshots {
+		components = append(components, newBackgroundComponentResponse(snapshot))
+		if !snapshot.Healthy() {
+			healthy = false
+		}
+	}
+	return components, healthy
+}
+
+// AdminComponentHealth lists the heartbeat state of background workers such
+// as the chat worker, upload processor, and session purger.
+func (h *Handler) AdminComponentHealth(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	if _, ok := h.requirePermission(w, r, authz.PlatformManage); !ok {
+		return
+	}
+	components, healthy := h.backgroundComponents()
+	status := "ok"
+	if !healthy {
+		status = "degraded"
+	}
+	WriteJSON(w, http.StatusOK, componentHealthResponse{
+		Status:      status,
+		Components:  components,
+		GeneratedAt: h.now().UTC().Format(time.RFC3339Nano),
+	})
+}
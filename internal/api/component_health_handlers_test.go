@@ -0,0 +1,106 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/authz"
+	"bitriver-live/internal/observability/health"
+	"bitriver-live/internal/storage"
+)
+
+// newComponentHealthFixture registers a healthy chat worker, a degraded
+// upload processor, and a session purger whose last heartbeat is stale.
+func newComponentHealthFixture(t *testing.T, now time.Time) (*Handler, *storage.Storage) {
+	t.Helper()
+	handler, store := newTestHandler(t)
+	clock := now
+	handler.Now = func() time.Time { return clock }
+	registry := health.NewRegistry(health.Config{StaleAfter: time.Minute, Now: func() time.Time { return clock }})
+	handler.ComponentHealth = registry
+
+	registry.RegisterComponent("session-purger").SetHealthy()
+	clock = now.Add(time.Minute)
+	registry.RegisterComponent("chat-worker").SetHealthy()
+	registry.RegisterComponent("uploads").SetDegraded(errors.New("list pending uploads: datastore unavailable"))
+	clock = now.Add(90 * time.Second)
+	return handler, store
+}
+
+func TestAdminComponentHealthPayload(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	handler, store := newComponentHealthFixture(t, now)
+	admin, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Admin", Email: "admin@example.com", Roles: []string{authz.RoleAdmin}})
+	if err != nil {
+		t.Fatalf("CreateUser admin: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.AdminComponentHealth(rec, withUser(httptest.NewRequest(http.MethodGet, "/api/admin/health/components", nil), admin))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	var payload struct {
+		Status      string           `json:"status"`
+		GeneratedAt string           `json:"generatedAt"`
+		Components  []map[string]any `json:"components"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode payload: %v", err)
+	}
+	if payload.Status != "degraded" || payload.GeneratedAt != "2024-05-01T12:01:30Z" {
+		t.Fatalf("unexpected summary %q at %q", payload.Status, payload.GeneratedAt)
+	}
+	want := []map[string]any{
+		{"name": "chat-worker", "status": "ok", "lastHeartbeat": "2024-05-01T12:01:00Z", "staleAfterSeconds": float64(60)},
+		{"name": "session-purger", "status": "stalled", "lastHeartbeat": "2024-05-01T12:00:00Z", "staleAfterSeconds": float64(60)},
+		{"name": "uploads", "status": "degraded", "lastHeartbeat": "2024-05-01T12:01:00Z", "lastError": "list pending uploads: datastore unavailable", "lastErrorAt": "2024-05-01T12:01:00Z", "staleAfterSeconds": float64(60)},
+	}
+	if len(payload.Components) != len(want) {
+		t.Fatalf("expected %d components, got %v", len(want), payload.Components)
+	}
+	for i, expected := range want {
+		got := payload.Components[i]
+		if len(got) != len(expected) {
+			t.Fatalf("component %d: expected fields %v, got %v", i, expected, got)
+		}
+		for key, value := range expected {
+			if got[key] != value {
+				t.Fatalf("component %d: expected %s=%v, got %v", i, key, value, got[key])
+			}
+		}
+	}
+}
+
+func TestReadyReportsBackgroundComponents(t *testing.T) {
+	handler, _ := newComponentHealthFixture(t, time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC))
+
+	ready := func() (int, map[string]any) {
+		rec := httptest.NewRecorder()
+		handler.Ready(rec, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+		var payload map[string]any
+		if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+			t.Fatalf("decode readiness: %v", err)
+		}
+		return rec.Code, payload
+	}
+
+	code, payload := ready()
+	if code != http.StatusOK || payload["status"] != "ok" {
+		t.Fatalf("expected informational background status to keep readiness ok, got %d %v", code, payload["status"])
+	}
+	if background, ok := payload["background"].([]any); !ok || len(background) != 3 {
+		t.Fatalf("expected background components in readiness payload, got %v", payload["background"])
+	}
+
+	handler.ReadyRequiresComponents = true
+	code, payload = ready()
+	if code != http.StatusServiceUnavailable || payload["status"] != "degraded" {
+		t.Fatalf("expected readiness to fail when components are required, got %d %v", code, payload["status"])
+	}
+}
@@ -4,6 +4,21 @@ import (
 	"net/http"
 	"strings"
 	"time"
+
+	"bitriver-live/internal/auth/oauth"
+)
+
+const (
+	sessionCookieName = "bitriver_session"
+	// impersonationCookieName carries an impersonation session alongside the
+	// administrator's own session cookie, which stays untouched so ending
+	// the impersonation returns them to their account.
+	impersonationCookieName = "bitriver_impersonation"
+	// oauthFlowCookieName carries the nonce an OAuth state is bound to, so
+	// only the browser that started a sign-in can finish it.
+	oauthFlowCookieName = "bitriver_oauth_flow"
+	// oauthFlowCookieTTL matches the OAuth manager's default state lifetime.
+	oauthFlowCookieTTL = 10 * time.Minute
 )
 
 type SessionCookieSecureMode int
@@ -44,6 +59,10 @@ func (h *Handler) sessionCookiePolicy() SessionCookiePolicy {
 }
 
 func setSessionCookie(w http.ResponseWriter, r *http.Request, token string, expires time.Time, policy SessionCookiePolicy) {
+	setAuthCookie(w, r, sessionCookieName, token, expires, policy)
+}
+
+func setAuthCookie(w http.ResponseWriter, r *http.Request, name, token string, expires time.Time, policy SessionCookiePolicy) {
 	if token == "" {
 		return
 	}
@@ -52,7 +71,7 @@ func setSessionCookie(w http.ResponseWriter, r *http.Request, token string, expi
 		maxAge = 0
 	}
 	http.SetCookie(w, &http.Cookie{
-		Name:     "bitriver_session",
+		Name:     name,
 		Value:    token,
 		Path:     "/",
 		Expires:  expires.UTC(),
@@ -74,8 +93,12 @@ func (h *Handler) RefreshSessionCookie(w http.ResponseWriter, r *http.Request, t
 }
 
 func clearSessionCookie(w http.ResponseWriter, r *http.Request, policy SessionCookiePolicy) {
+	clearAuthCookie(w, r, sessionCookieName, policy)
+}
+
+func clearAuthCookie(w http.ResponseWriter, r *http.Request, name string, policy SessionCookiePolicy) {
 	http.SetCookie(w, &http.Cookie{
-		Name:     "bitriver_session",
+		Name:     name,
 		Value:    "",
 		Path:     "/",
 		Expires:  time.Unix(0, 0).UTC(),
@@ -86,6 +109,29 @@ func clearSessionCookie(w http.ResponseWriter, r *http.Request, policy SessionCo
 	})
 }
 
+// setOAuthFlowCookie stores the OAuth flow nonce. The provider sends the
+// browser back with a cross-site navigation, which SameSite=Strict cookies
+// do not survive, so the cookie is Lax whatever the session policy says.
+func setOAuthFlowCookie(w http.ResponseWriter, r *http.Request, nonce string, expires time.Time, policy SessionCookiePolicy) {
+	policy.SameSite = http.SameSiteLaxMode
+	setAuthCookie(w, r, oauthFlowCookieName, nonce, expires, policy)
+}
+
+func clearOAuthFlowCookie(w http.ResponseWriter, r *http.Request, policy SessionCookiePolicy) {
+	policy.SameSite = http.SameSiteLaxMode
+	clearAuthCookie(w, r, oauthFlowCookieName, policy)
+}
+
+// oauthFlowBinding returns what an OAuth callback presents to the manager:
+// the flow cookie set at start, if the browser sent it, and the client IP.
+func oauthFlowBinding(r *http.Request) oauth.Binding {
+	binding := oauth.Binding{IP: requestClientIP(r)}
+	if cookie, err := r.Cookie(oauthFlowCookieName); err == nil {
+		binding.Session = cookie.Value
+	}
+	return binding
+}
+
 // ClearSessionCookie removes the BitRiver session cookie from the response.
 func ClearSessionCookie(w http.ResponseWriter, r *http.Request, policy SessionCookiePolicy) {
 	clearSessionCookie(w, r, policy)
@@ -96,6 +142,23 @@ func (h *Handler) ClearSessionCookie(w http.ResponseWriter, r *http.Request) {
 	clearSessionCookie(w, r, h.sessionCookiePolicy())
 }
 
+// ClearImpersonationCookie removes the impersonation cookie from the response,
+// leaving the administrator's own session cookie in place.
+func (h *Handler) ClearImpersonationCookie(w http.ResponseWriter, r *http.Request) {
+	clearAuthCookie(w, r, impersonationCookieName, h.sessionCookiePolicy())
+}
+
+// UsesImpersonationCookie reports whether the request's session token comes
+// from the impersonation cookie rather than the Authorization header or the
+// regular session cookie.
+func UsesImpersonationCookie(r *http.Request) bool {
+	if bearerToken(r) != "" {
+		return false
+	}
+	cookie, err := r.Cookie(impersonationCookieName)
+	return err == nil && cookie.Value != ""
+}
+
 func isSecureRequest(r *http.Request) bool {
 	if r == nil {
 		return false
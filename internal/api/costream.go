@@ -0,0 +1,217 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/models"
+)
+
+type coStreamInviteRequest struct {
+	GuestChannelID string `json:"guestChannelId"`
+}
+
+type coStreamInviteResponse struct {
+	ID             string  `json:"id"`
+	SessionID      string  `json:"sessionId"`
+	HostChannelID  string  `json:"hostChannelId"`
+	GuestChannelID string  `json:"guestChannelId"`
+	InvitedBy      string  `json:"invitedBy,omitempty"`
+	Status         string  `json:"status"`
+	CreatedAt      string  `json:"createdAt"`
+	RespondedAt    *string `json:"respondedAt,omitempty"`
+	EndedAt        *string `json:"endedAt,omitempty"`
+}
+
+// coStreamResponse marks a guest channel that mirrors the host's live
+// session, so pages can label it "co-streaming with" the host.
+type coStreamResponse struct {
+	HostChannelID   string `json:"hostChannelId"`
+	HostTitle       string `json:"hostTitle"`
+	HostDisplayName string `json:"hostDisplayName,omitempty"`
+	SessionID       string `json:"sessionId"`
+}
+
+func newCoStreamInviteResponse(invite models.CoStreamInvite) coStreamInviteResponse {
+	resp := coStreamInviteResponse{
+		ID:             invite.ID,
+		SessionID:      invite.SessionID,
+		HostChannelID:  invite.HostChannelID,
+		GuestChannelID: invite.GuestChannelID,
+		InvitedBy:      invite.InvitedBy,
+		Status:         invite.Status,
+		CreatedAt:      invite.CreatedAt.Format(time.RFC3339Nano),
+	}
+	if invite.RespondedAt != nil {
+		responded := invite.RespondedAt.Format(time.RFC3339Nano)
+		resp.RespondedAt = &responded
+	}
+	if invite.EndedAt != nil {
+		ended := invite.EndedAt.Format(time.RFC3339Nano)
+		resp.EndedAt = &ended
+	}
+	return resp
+}
+
+func (h *Handler) newCoStreamResponse(invite models.CoStreamInvite, host models.Channel) *coStreamResponse {
+	resp := &coStreamResponse{
+		HostChannelID: host.ID,
+		HostTitle:     host.Title,
+		SessionID:     invite.SessionID,
+	}
+	if owner, ok := h.Store.GetUser(host.OwnerID); ok {
+		resp.HostDisplayName = owner.DisplayName
+	}
+	return resp
+}
+
+// activeCoStreams maps each guest channel to the co-stream it mirrors.
+func (h *Handler) activeCoStreams() map[string]models.CoStreamInvite {
+	invites, err := h.Store.ListActiveCoStreams()
+	if err != nil {
+		h.logger().Warn("failed to list active co-streams", "error", err)
+		return nil
+	}
+	guests := make(map[string]models.CoStreamInvite, len(invites))
+	for _, invite := range invites {
+		guests[invite.GuestChannelID] = invite
+	}
+	return guests
+}
+
+// coStreamSource returns the co-stream the guest channel mirrors together
+// with the host channel and its live session.
+func (h *Handler) coStreamSource(guestChannelID string) (models.CoStreamInvite, models.Channel, models.StreamSession, bool) {
+	invites, err := h.Store.ListCoStreamInvites(guestChannelID)
+	if err != nil {
+		h.logger().Warn("failed to list co-stream invites", "channel_id", guestChannelID, "error", err)
+		return models.CoStreamInvite{}, models.Channel{}, models.StreamSession{}, false
+	}
+	for _, invite := range invites {
+		if invite.GuestChannelID != guestChannelID || invite.Status != models.CoStreamInviteAccepted {
+			continue
+		}
+		host, ok := h.Store.GetChannel(invite.HostChannelID)
+		if !ok {
+			continue
+		}
+		session, live := h.Store.CurrentStreamSession(host.ID)
+		if !live || session.ID != invite.SessionID {
+			continue
+		}
+		return invite, host, session, true
+	}
+	return models.CoStreamInvite{}, models.Channel{}, models.StreamSession{}, false
+}
+
+// handleCoStreamInvites serves /api/channels/{id}/costream/invites. The
+// host's managers list and send invites to guest channels and remove
+// (DELETE) an invite or guest; the guest's managers list their invites,
+// accept or decline one with POST .../invites/{inviteId}/accept or
+// .../decline, and leave with DELETE. An accepted guest's channel page
+// plays the host's session until the host stops or removes the guest.
+func (h *Handler) handleCoStreamInvites(channel models.Channel, remaining []string, w http.ResponseWriter, r *http.Request) {
+	if len(remaining) == 0 || remaining[0] != "invites" || len(remaining) > 3 {
+		WriteError(w, http.StatusNotFound, fmt.Errorf("unknown costream path"))
+		return
+	}
+	user, ok := h.ensureChannelAccess(w, r, channel)
+	if !ok {
+		return
+	}
+
+	if len(remaining) == 1 || strings.TrimSpace(remaining[1]) == "" {
+		switch r.Method {
+		case http.MethodGet:
+			invites, err := h.Store.ListCoStreamInvites(channel.ID)
+			if err != nil {
+				writeStorageError(w, http.StatusInternalServerError, err)
+				return
+			}
+			response := make([]coStreamInviteResponse, 0, len(invites))
+			for _, invite := range invites {
+				response = append(response, newCoStreamInviteResponse(invite))
+			}
+			WriteJSON(w, http.StatusOK, response)
+		case http.MethodPost:
+			var req coStreamInviteRequest
+			if !DecodeAndValidate(w, r, &req) {
+				return
+			}
+			invite, err := h.Store.CreateCoStreamInvite(channel.ID, req.GuestChannelID, user.ID)
+			if err != nil {
+				writeStorageError(w, http.StatusBadRequest, err)
+				return
+			}
+			WriteJSON(w, http.StatusCreated, newCoStreamInviteResponse(invite))
+		default:
+			WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodPost)
+		}
+		return
+	}
+
+	invite, ok := h.Store.GetCoStreamInvite(strings.TrimSpace(remaining[1]))
+	if !ok || (invite.HostChannelID != channel.ID && invite.GuestChannelID != channel.ID) {
+		WriteError(w, http.StatusNotFound, fmt.Errorf("co-stream invite %s not found", remaining[1]))
+		return
+	}
+
+	if len(remaining) == 3 {
+		if remaining[2] != "accept" && remaining[2] != "decline" {
+			WriteError(w, http.StatusNotFound, fmt.Errorf("unknown costream path"))
+			return
+		}
+		if r.Method != http.MethodPost {
+			WriteMethodNotAllowed(w, r, http.MethodPost)
+			return
+		}
+		// Only the invited channel answers; the host cannot accept on
+		// its behalf.
+		if invite.GuestChannelID != channel.ID {
+			WriteError(w, http.StatusForbidden, fmt.Errorf("only the invited channel can respond"))
+			return
+		}
+		updated, err := h.Store.RespondCoStreamInvite(invite.ID, remaining[2] == "accept")
+		if err != nil {
+			writeStorageError(w, http.StatusBadRequest, err)
+			return
+		}
+		h.invalidateChannelCache(r.Context(), updated.GuestChannelID)
+		WriteJSON(w, http.StatusOK, newCoStreamInviteResponse(updated))
+		return
+	}
+
+	if r.Method != http.MethodDelete {
+		WriteMethodNotAllowed(w, r, http.MethodDelete)
+		return
+	}
+	updated, err := h.Store.EndCoStreamInvite(invite.ID)
+	if err != nil {
+		writeStorageError(w, http.StatusBadRequest, err)
+		return
+	}
+	h.invalidateChannelCache(r.Context(), updated.GuestChannelID)
+	WriteJSON(w, http.StatusOK, newCoStreamInviteResponse(updated))
+}
+
+// coStreamGuestChannels returns the channels mirroring a live co-stream
+// that are not already among the live channels.
+func (h *Handler) coStreamGuestChannels(channels, live []models.Channel) []models.Channel {
+	guests := h.activeCoStreams()
+	if len(guests) == 0 {
+		return nil
+	}
+	listed := make(map[string]bool, len(live))
+	for _, channel := range live {
+		listed[channel.ID] = true
+	}
+	mirrored := make([]models.Channel, 0, len(guests))
+	for _, channel := range channels {
+		if _, ok := guests[channel.ID]; ok && !listed[channel.ID] {
+			mirrored = append(mirrored, channel)
+		}
+	}
+	return mirrored
+}
@@ -0,0 +1,178 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+func TestCoStreamInviteLifecycleAndMirroring(t *testing.T) {
+	h, store := newTestHandler(t)
+	hostOwner, host := newStatusChannel(t, store)
+	guestOwner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Guest", Email: "guest@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	guest, err := store.CreateChannel(guestOwner.ID, "Guest Show", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	request := func(method, path string, user *models.User, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/channels/"+path, strings.NewReader(body))
+		if user != nil {
+			req = withUser(req, *user)
+		}
+		rec := httptest.NewRecorder()
+		h.ChannelByID(rec, req)
+		return rec
+	}
+	playback := func() channelPlaybackResponse {
+		t.Helper()
+		rec := request(http.MethodGet, guest.ID+"/playback", &viewer, "")
+		if rec.Code != http.StatusOK {
+			t.Fatalf("playback: %d %s", rec.Code, rec.Body.String())
+		}
+		var payload channelPlaybackResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+			t.Fatalf("decode playback: %v", err)
+		}
+		return payload
+	}
+
+	invitesPath := host.ID + "/costream/invites"
+	body := `{"guestChannelId":"` + guest.ID + `"}`
+	if rec := request(http.MethodPost, invitesPath, &hostOwner, body); rec.Code != http.StatusConflict {
+		t.Fatalf("expected an offline host to be refused, got %d", rec.Code)
+	}
+	session, err := store.StartStream(host.ID, []string{"720p"})
+	if err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	if rec := request(http.MethodPost, invitesPath, &guestOwner, body); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected the guest to be refused inviting for the host, got %d", rec.Code)
+	}
+	rec := request(http.MethodPost, invitesPath, &hostOwner, body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected the host to invite the guest, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var invite coStreamInviteResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &invite); err != nil {
+		t.Fatalf("decode invite: %v", err)
+	}
+	if invite.Status != models.CoStreamInvitePending || invite.SessionID != session.ID {
+		t.Fatalf("expected a pending invite into the live session, got %+v", invite)
+	}
+
+	if payload := playback(); payload.Live || payload.CoStream != nil || payload.Playback != nil {
+		t.Fatalf("expected a pending invite not to mirror the host, got %+v", payload)
+	}
+	if rec := request(http.MethodPost, host.ID+"/costream/invites/"+invite.ID+"/accept", &hostOwner, ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected the host to be refused accepting for the guest, got %d", rec.Code)
+	}
+	if rec := request(http.MethodPost, guest.ID+"/costream/invites/"+invite.ID+"/accept", &viewer, ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected a viewer to be refused, got %d", rec.Code)
+	}
+	if rec := request(http.MethodPost, guest.ID+"/costream/invites/"+invite.ID+"/accept", &guestOwner, ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected the guest to accept, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	payload := playback()
+	if !payload.Live || payload.Playback == nil || payload.Playback.SessionID != session.ID {
+		t.Fatalf("expected the guest page to play the host's session, got %+v", payload)
+	}
+	if payload.CoStream == nil || payload.CoStream.HostChannelID != host.ID || payload.CoStream.HostDisplayName != hostOwner.DisplayName {
+		t.Fatalf("expected the guest page to credit the host, got %+v", payload.CoStream)
+	}
+	if payload.Channel.ID != guest.ID {
+		t.Fatalf("expected the guest's own channel details, got %+v", payload.Channel)
+	}
+
+	channels, err := h.liveChannels()
+	if err != nil {
+		t.Fatalf("liveChannels: %v", err)
+	}
+	entries := h.buildDirectoryResponse(channels).Channels
+	if len(entries) != 2 {
+		t.Fatalf("expected the host and guest listed, got %+v", entries)
+	}
+	byID := map[string]directoryChannelResponse{}
+	for _, entry := range entries {
+		byID[entry.Channel.ID] = entry
+	}
+	if entry := byID[host.ID]; !entry.Live || entry.CoStream != nil {
+		t.Fatalf("expected the host listed as live, got %+v", entry)
+	}
+	if entry := byID[guest.ID]; !entry.Live || entry.CoStream == nil || entry.CoStream.SessionID != session.ID {
+		t.Fatalf("expected the guest listed on the host's session, got %+v", entry)
+	}
+
+	rec = request(http.MethodGet, guest.ID+"/costream/invites", &guestOwner, "")
+	var listed []coStreamInviteResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &listed); err != nil || rec.Code != http.StatusOK || len(listed) != 1 {
+		t.Fatalf("expected the guest to list its co-stream, got %d %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := request(http.MethodDelete, host.ID+"/costream/invites/"+invite.ID, &hostOwner, ""); rec.Code != http.StatusOK {
+		t.Fatalf("expected the host to remove the guest, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if payload := playback(); payload.Live || payload.CoStream != nil {
+		t.Fatalf("expected removing the guest to stop the mirroring, got %+v", payload)
+	}
+}
+
+func TestCoStreamMirroringEndsWhenHostStops(t *testing.T) {
+	h, store := newTestHandler(t)
+	hostOwner, host := newStatusChannel(t, store)
+	guestOwner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Guest", Email: "guest@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	guest, err := store.CreateChannel(guestOwner.ID, "Guest Show", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	if _, err := store.StartStream(host.ID, []string{"720p"}); err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	invite, err := store.CreateCoStreamInvite(host.ID, guest.ID, hostOwner.ID)
+	if err != nil {
+		t.Fatalf("CreateCoStreamInvite: %v", err)
+	}
+	if _, err := store.RespondCoStreamInvite(invite.ID, true); err != nil {
+		t.Fatalf("RespondCoStreamInvite: %v", err)
+	}
+	if _, err := store.StopStream(host.ID, 0); err != nil {
+		t.Fatalf("StopStream: %v", err)
+	}
+
+	channels, err := h.liveChannels()
+	if err != nil {
+		t.Fatalf("liveChannels: %v", err)
+	}
+	if len(channels) != 0 {
+		t.Fatalf("expected neither channel listed once the host stops, got %+v", channels)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/channels/"+guest.ID+"/playback", nil)
+	rec := httptest.NewRecorder()
+	h.ChannelByID(rec, req)
+	var payload channelPlaybackResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode playback: %v", err)
+	}
+	if payload.Live || payload.CoStream != nil || payload.Playback != nil {
+		t.Fatalf("expected the guest page offline once the host stops, got %+v", payload)
+	}
+	if stored, ok := store.GetCoStreamInvite(invite.ID); !ok || stored.Status != models.CoStreamInviteEnded {
+		t.Fatalf("expected the co-stream marked ended, got %+v", stored)
+	}
+}
@@ -0,0 +1,171 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+	"bitriver-live/internal/wallet"
+)
+
+// donationChallengeTTL bounds how long an issued verification message can
+// be signed and submitted.
+const donationChallengeTTL = 15 * time.Minute
+
+// donationChallengeKeyLabel derives the key that signs verification
+// messages from CookieSigningKey.
+const donationChallengeKeyLabel = "donation-address-verification"
+
+type verifyDonationAddressRequest struct {
+	// Address picks one of several addresses listed for the currency.
+	Address string `json:"address"`
+	// Message and Signature are omitted to request a message, then sent
+	// back with the wallet's personal_sign signature of that message.
+	Message   string `json:"message"`
+	Signature string `json:"signature"`
+}
+
+type donationChallengeResponse struct {
+	Currency  string `json:"currency"`
+	Address   string `json:"address"`
+	Message   string `json:"message"`
+	ExpiresAt string `json:"expiresAt"`
+}
+
+// verifyDonationAddress serves POST
+// /api/users/me/donation-addresses/{currency}/verify. A request without a
+// signature returns a message, signed by the server, for the owner to sign
+// with the wallet holding the address; sending that message back with the
+// wallet signature marks the address verified. The server signature lets
+// any instance check the message without storing it.
+func (h *Handler) verifyDonationAddress(w http.ResponseWriter, r *http.Request, rawCurrency string) {
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+	user, ok := h.requireAuthenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	currency, err := storage.ParseCryptoCurrency(rawCurrency)
+	if err != nil {
+		WriteRequestError(w, ValidationError(err.Error()))
+		return
+	}
+	if !storage.DonationAddressVerifiable(currency) {
+		WriteError(w, http.StatusUnprocessableEntity, storage.ErrDonationAddressUnverifiable)
+		return
+	}
+	if len(h.CookieSigningKey) == 0 {
+		WriteRequestError(w, RequestError{Status: http.StatusNotImplemented, CodeVal: "secret_key_unconfigured", Message: "verifying donation addresses requires a server secret key"})
+		return
+	}
+	var req verifyDonationAddressRequest
+	if !DecodeAndValidate(w, r, &req) {
+		return
+	}
+	profile, _ := h.Store.GetProfile(user.ID)
+	addr, err := storage.FindDonationAddress(profile, currency, req.Address)
+	if err != nil {
+		if errors.Is(err, storage.ErrDonationAddressNotFound) {
+			writeStorageError(w, http.StatusNotFound, err)
+			return
+		}
+		WriteRequestError(w, ValidationError(err.Error()))
+		return
+	}
+
+	now := h.now().UTC()
+	if strings.TrimSpace(req.Signature) == "" {
+		nonce := make([]byte, 16)
+		if _, err := rand.Read(nonce); err != nil {
+			writeStorageError(w, http.StatusInternalServerError, err)
+			return
+		}
+		expiresAt := now.Add(donationChallengeTTL).Truncate(time.Second)
+		WriteJSON(w, http.StatusOK, donationChallengeResponse{
+			Currency:  string(addr.Currency),
+			Address:   addr.Address,
+			Message:   h.donationChallengeMessage(user.ID, addr, hex.EncodeToString(nonce), expiresAt),
+			ExpiresAt: expiresAt.Format(time.RFC3339),
+		})
+		return
+	}
+
+	nonce, expiresAt, ok := parseDonationChallenge(req.Message)
+	if !ok || !hmac.Equal([]byte(req.Message), []byte(h.donationChallengeMessage(user.ID, addr, nonce, expiresAt))) {
+		WriteRequestError(w, ValidationError("message was not issued for this address"))
+		return
+	}
+	if !now.Before(expiresAt) {
+		WriteRequestError(w, ValidationError("message has expired; request a new one"))
+		return
+	}
+	signer, err := wallet.RecoverEthereumSigner(req.Message, req.Signature)
+	if err != nil {
+		WriteRequestError(w, ValidationError(fmt.Sprintf("invalid signature: %v", err)))
+		return
+	}
+	if signer != addr.Address {
+		WriteRequestError(w, ValidationError("signature was not made by the wallet holding this address"))
+		return
+	}
+
+	profile, err = h.Store.VerifyDonationAddress(user.ID, currency, addr.Address, now)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrDonationAddressNotFound):
+			writeStorageError(w, http.StatusNotFound, err)
+		default:
+			writeStorageError(w, http.StatusInternalServerError, err)
+		}
+		return
+	}
+	h.invalidateDirectoryCache(r.Context())
+	verified, err := storage.FindDonationAddress(profile, currency, addr.Address)
+	if err != nil {
+		writeStorageError(w, http.StatusInternalServerError, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, newCryptoAddressResponse(verified))
+}
+
+// donationChallengeMessage renders the message a wallet signs to prove it
+// holds addr. Its last line signs the rest with a key derived from
+// CookieSigningKey, so a message can only be one this server issued.
+func (h *Handler) donationChallengeMessage(userID string, addr models.CryptoAddress, nonce string, expiresAt time.Time) string {
+	body := fmt.Sprintf("BitRiver Live donation address verification\n\nSign this message to prove you control the %s address below.\n\nAddress: %s\nAccount: %s\nNonce: %s\nExpires: %s\n",
+		addr.Currency, addr.Address, userID, nonce, expiresAt.UTC().Format(time.RFC3339))
+	derive := hmac.New(sha256.New, h.CookieSigningKey)
+	derive.Write([]byte(donationChallengeKeyLabel))
+	mac := hmac.New(sha256.New, derive.Sum(nil))
+	mac.Write([]byte(body))
+	return body + "Challenge: " + hex.EncodeToString(mac.Sum(nil))
+}
+
+// parseDonationChallenge extracts the nonce and expiry of a verification
+// message so it can be rebuilt and compared.
+func parseDonationChallenge(message string) (string, time.Time, bool) {
+	var nonce, expires string
+	for _, line := range strings.Split(message, "\n") {
+		if value, ok := strings.CutPrefix(line, "Nonce: "); ok {
+			nonce = value
+		}
+		if value, ok := strings.CutPrefix(line, "Expires: "); ok {
+			expires = value
+		}
+	}
+	expiresAt, err := time.Parse(time.RFC3339, expires)
+	if nonce == "" || err != nil {
+		return "", time.Time{}, false
+	}
+	return nonce, expiresAt, true
+}
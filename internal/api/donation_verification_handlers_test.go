@@ -0,0 +1,187 @@
+package api
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+	"bitriver-live/internal/wallet"
+)
+
+// Key and address of the web3.js accounts documentation example.
+const (
+	donationWalletKey     = "4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318"
+	donationWalletAddress = "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23"
+)
+
+func newDonationVerificationFixture(t *testing.T) (*Handler, models.User, *time.Time) {
+	t.Helper()
+	handler, store := newTestHandler(t)
+	handler.CookieSigningKey = []byte("donation-test-secret")
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	handler.Now = func() time.Time { return now }
+	user, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Donee", Email: "donee@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	addresses := []models.CryptoAddress{
+		{Currency: "ETH", Address: strings.ToLower(donationWalletAddress)},
+		{Currency: "BTC", Address: "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"},
+	}
+	if _, err := store.UpsertProfile(user.ID, storage.ProfileUpdate{DonationAddresses: &addresses}); err != nil {
+		t.Fatalf("UpsertProfile: %v", err)
+	}
+	return handler, user, &now
+}
+
+func postDonationVerification(t *testing.T, handler *Handler, user models.User, currency string, payload any) *httptest.ResponseRecorder {
+	t.Helper()
+	body, _ := json.Marshal(payload)
+	req := httptest.NewRequest(http.MethodPost, "/api/users/me/donation-addresses/"+currency+"/verify", bytes.NewReader(body))
+	req = withUser(req, user)
+	rec := httptest.NewRecorder()
+	handler.UserByID(rec, req)
+	return rec
+}
+
+func issueDonationChallenge(t *testing.T, handler *Handler, user models.User) donationChallengeResponse {
+	t.Helper()
+	rec := postDonationVerification(t, handler, user, "eth", map[string]string{})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected challenge status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var challenge donationChallengeResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &challenge); err != nil {
+		t.Fatalf("decode challenge: %v", err)
+	}
+	return challenge
+}
+
+func signDonationChallenge(t *testing.T, message string) string {
+	t.Helper()
+	key, _ := hex.DecodeString(donationWalletKey)
+	signature, err := wallet.SignPersonalMessage(key, message)
+	if err != nil {
+		t.Fatalf("SignPersonalMessage: %v", err)
+	}
+	return signature
+}
+
+func TestVerifyDonationAddressMarksAddressVerified(t *testing.T) {
+	handler, user, _ := newDonationVerificationFixture(t)
+
+	challenge := issueDonationChallenge(t, handler, user)
+	if challenge.Address != donationWalletAddress || !strings.Contains(challenge.Message, donationWalletAddress) {
+		t.Fatalf("expected a challenge for %s, got %+v", donationWalletAddress, challenge)
+	}
+
+	rec := postDonationVerification(t, handler, user, "eth", map[string]string{
+		"message":   challenge.Message,
+		"signature": signDonationChallenge(t, challenge.Message),
+	})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected verify status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var verified cryptoAddressResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &verified); err != nil {
+		t.Fatalf("decode verified address: %v", err)
+	}
+	if !verified.Verified || verified.VerifiedAt == nil {
+		t.Fatalf("expected the address to be verified, got %+v", verified)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/profiles/"+user.ID, nil)
+	rec = httptest.NewRecorder()
+	handler.ProfileByID(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected profile status 200, got %d", rec.Code)
+	}
+	var profile profileViewResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &profile); err != nil {
+		t.Fatalf("decode profile: %v", err)
+	}
+	if len(profile.DonationAddresses) != 2 || !profile.DonationAddresses[0].Verified || profile.DonationAddresses[1].Verified {
+		t.Fatalf("expected only the ETH address flagged verified on the public profile, got %+v", profile.DonationAddresses)
+	}
+}
+
+func TestVerifyDonationAddressRejectsBadProofs(t *testing.T) {
+	otherKey, _ := hex.DecodeString("b71c71a67e1177ad4e901695e1b4b9ee17ae16c6668d313eac2f96dbcda3f291")
+
+	cases := []struct {
+		name   string
+		mutate func(t *testing.T, now *time.Time, challenge donationChallengeResponse) map[string]string
+	}{
+		{
+			name: "signed by another wallet",
+			mutate: func(t *testing.T, _ *time.Time, challenge donationChallengeResponse) map[string]string {
+				signature, err := wallet.SignPersonalMessage(otherKey, challenge.Message)
+				if err != nil {
+					t.Fatalf("SignPersonalMessage: %v", err)
+				}
+				return map[string]string{"message": challenge.Message, "signature": signature}
+			},
+		},
+		{
+			name: "message not issued by the server",
+			mutate: func(t *testing.T, _ *time.Time, challenge donationChallengeResponse) map[string]string {
+				forged := strings.Replace(challenge.Message, "Nonce: ", "Nonce: 00", 1)
+				return map[string]string{"message": forged, "signature": signDonationChallenge(t, forged)}
+			},
+		},
+		{
+			name: "expired message",
+			mutate: func(t *testing.T, now *time.Time, challenge donationChallengeResponse) map[string]string {
+				*now = now.Add(donationChallengeTTL + time.Second)
+				return map[string]string{"message": challenge.Message, "signature": signDonationChallenge(t, challenge.Message)}
+			},
+		},
+		{
+			name: "malformed signature",
+			mutate: func(t *testing.T, _ *time.Time, challenge donationChallengeResponse) map[string]string {
+				return map[string]string{"message": challenge.Message, "signature": "0x1234"}
+			},
+		},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			handler, user, now := newDonationVerificationFixture(t)
+			challenge := issueDonationChallenge(t, handler, user)
+			rec := postDonationVerification(t, handler, user, "eth", tc.mutate(t, now, challenge))
+			if rec.Code != http.StatusBadRequest {
+				t.Fatalf("expected status 400, got %d: %s", rec.Code, rec.Body.String())
+			}
+			profile, _ := handler.Store.GetProfile(user.ID)
+			if profile.DonationAddresses[0].VerifiedAt != nil {
+				t.Fatal("expected the address to stay unverified")
+			}
+		})
+	}
+}
+
+func TestVerifyDonationAddressSkipsUnverifiableCurrencies(t *testing.T) {
+	handler, user, _ := newDonationVerificationFixture(t)
+
+	rec := postDonationVerification(t, handler, user, "btc", map[string]string{})
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected status 422 for BTC, got %d", rec.Code)
+	}
+	rec = postDonationVerification(t, handler, user, "doge", map[string]string{})
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected status 400 for an unsupported currency, got %d", rec.Code)
+	}
+
+	handler.CookieSigningKey = nil
+	rec = postDonationVerification(t, handler, user, "eth", map[string]string{})
+	if rec.Code != http.StatusNotImplemented {
+		t.Fatalf("expected status 501 without a secret key, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,384 @@
+package api
+
+import (
+	"bytes"
+	"fmt"
+	"html/template"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"bitriver-live/internal/models"
+)
+
+const (
+	// embedProviderName identifies the platform in oEmbed payloads.
+	embedProviderName = "BitRiver Live"
+	// embedPathPrefix serves the chrome-less player that third-party sites
+	// frame. It is the only route the security headers allow to be framed.
+	embedPathPrefix = "/embed/"
+	// viewerChannelPathPrefix is where the viewer serves channel pages under
+	// its default base path. Embeds link there for restricted or offline
+	// channels.
+	viewerChannelPathPrefix = "/viewer/channels/"
+	// embedDefaultWidth and embedDefaultHeight size the iframe when the
+	// consumer sets no limits. Smaller limits keep the 16:9 aspect.
+	embedDefaultWidth  = 640
+	embedDefaultHeight = 360
+	// oEmbedLiveCacheAge and oEmbedCacheAge tell consumers how long to keep a
+	// payload. Live channels change title and thumbnail more often.
+	oEmbedLiveCacheAge = 60
+	oEmbedCacheAge     = 3600
+)
+
+// oEmbedResponse is a video response as defined by the oEmbed 1.0 spec,
+// plus is_live so consumers can badge live channels.
+type oEmbedResponse struct {
+	Version         string `json:"version"`
+	Type            string `json:"type"`
+	ProviderName    string `json:"provider_name"`
+	ProviderURL     string `json:"provider_url"`
+	Title           string `json:"title"`
+	AuthorName      string `json:"author_name,omitempty"`
+	AuthorURL       string `json:"author_url,omitempty"`
+	ThumbnailURL    string `json:"thumbnail_url,omitempty"`
+	ThumbnailWidth  int    `json:"thumbnail_width,omitempty"`
+	ThumbnailHeight int    `json:"thumbnail_height,omitempty"`
+	HTML            string `json:"html"`
+	Width           int    `json:"width"`
+	Height          int    `json:"height"`
+	CacheAge        int    `json:"cache_age,omitempty"`
+	IsLive          bool   `json:"is_live"`
+}
+
+// embedTarget is the channel, and optionally the recording, an oEmbed URL
+// or embed request points at.
+type embedTarget struct {
+	channelID   string
+	recordingID string
+}
+
+// parseEmbedURL recognises channel, recording, and embed URLs by their
+// trailing path segments, so viewer deployments under any base path work:
+// .../channels/{id}, .../recordings/{id}, and /embed/{id}?recording={id}.
+func parseEmbedURL(raw string) (embedTarget, bool) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil || parsed.Host == "" {
+		return embedTarget{}, false
+	}
+	if scheme := strings.ToLower(parsed.Scheme); scheme != "http" && scheme != "https" {
+		return embedTarget{}, false
+	}
+	segments := strings.Split(strings.Trim(parsed.Path, "/"), "/")
+	if len(segments) < 2 {
+		return embedTarget{}, false
+	}
+	kind, id := segments[len(segments)-2], strings.TrimSpace(segments[len(segments)-1])
+	if id == "" {
+		return embedTarget{}, false
+	}
+	switch kind {
+	case "channels":
+		return embedTarget{channelID: id}, true
+	case "recordings":
+		return embedTarget{recordingID: id}, true
+	case "embed":
+		return embedTarget{channelID: id, recordingID: strings.TrimSpace(parsed.Query().Get("recording"))}, true
+	}
+	return embedTarget{}, false
+}
+
+// embeddable reports whether a channel's media may play inside a
+// third-party page. Mature and restricted channels need the viewer's
+// session and age confirmation, which an embed cannot carry.
+func embeddable(channel models.Channel) bool {
+	return !channel.MatureContent && channel.PlaybackRestriction == ""
+}
+
+// requestBaseURL returns the scheme and host the client used to reach the
+// server.
+func requestBaseURL(r *http.Request) url.URL {
+	host := r.Host
+	if host == "" && r.URL != nil {
+		host = r.URL.Host
+	}
+	if host == "" {
+		host = "localhost"
+	}
+	return url.URL{Scheme: requestScheme(r), Host: host}
+}
+
+func absoluteURL(base url.URL, path string, query url.Values) string {
+	base.Path = path
+	if len(query) > 0 {
+		base.RawQuery = query.Encode()
+	}
+	return base.String()
+}
+
+// embedDimensions fits the default player size inside the consumer's
+// maxwidth and maxheight, keeping 16:9.
+func embedDimensions(maxWidth, maxHeight int) (int, int) {
+	width, height := embedDefaultWidth, embedDefaultHeight
+	if maxWidth > 0 && maxWidth < width {
+		width = maxWidth
+		height = width * 9 / 16
+	}
+	if maxHeight > 0 && maxHeight < height {
+		height = maxHeight
+		width = height * 16 / 9
+	}
+	return width, height
+}
+
+func parseOEmbedLimit(r *http.Request, name string) (int, error) {
+	raw := strings.TrimSpace(r.URL.Query().Get(name))
+	if raw == "" {
+		return 0, nil
+	}
+	value, err := strconv.Atoi(raw)
+	if err != nil || value <= 0 {
+		return 0, ValidationError(fmt.Sprintf("%s must be a positive integer", name))
+	}
+	return value, nil
+}
+
+// OEmbed serves /api/oembed for channel and recording URLs so sites and
+// chat apps can turn a pasted link into an embedded player. Only the JSON
+// format is offered; unknown URLs, unpublished recordings, and missing
+// channels get a 404 as the spec requires.
+func (h *Handler) OEmbed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodHead)
+		return
+	}
+	query := r.URL.Query()
+	rawURL := strings.TrimSpace(query.Get("url"))
+	if rawURL == "" {
+		WriteRequestError(w, ValidationError("url is required"))
+		return
+	}
+	if format := strings.ToLower(strings.TrimSpace(query.Get("format"))); format != "" && format != "json" {
+		WriteRequestError(w, RequestError{Status: http.StatusNotImplemented, CodeVal: "format_unsupported", Message: "only the json format is supported"})
+		return
+	}
+	maxWidth, err := parseOEmbedLimit(r, "maxwidth")
+	if err != nil {
+		WriteRequestError(w, err)
+		return
+	}
+	maxHeight, err := parseOEmbedLimit(r, "maxheight")
+	if err != nil {
+		WriteRequestError(w, err)
+		return
+	}
+
+	target, ok := parseEmbedURL(rawURL)
+	if !ok {
+		WriteError(w, http.StatusNotFound, fmt.Errorf("no embed available for %s", rawURL))
+		return
+	}
+	channel, recording, ok := h.resolveEmbedTarget(target)
+	if !ok {
+		WriteError(w, http.StatusNotFound, fmt.Errorf("no embed available for %s", rawURL))
+		return
+	}
+	owner, _ := h.Store.GetUser(channel.OwnerID)
+
+	base := requestBaseURL(r)
+	width, height := embedDimensions(maxWidth, maxHeight)
+	response := oEmbedResponse{
+		Version:      "1.0",
+		Type:         "video",
+		ProviderName: embedProviderName,
+		ProviderURL:  absoluteURL(base, "/", nil),
+		Title:        channel.Title,
+		AuthorName:   owner.DisplayName,
+		AuthorURL:    absoluteURL(base, viewerChannelPathPrefix+channel.ID, nil),
+		Width:        width,
+		Height:       height,
+		CacheAge:     oEmbedCacheAge,
+	}
+	var embedQuery url.Values
+	if recording != nil {
+		embedQuery = url.Values{"recording": {recording.ID}}
+		response.Title = recording.Title
+		if embeddable(channel) {
+			for _, thumb := range recording.Thumbnails {
+				if thumb.URL != "" && thumb.Width > 0 && thumb.Height > 0 {
+					response.ThumbnailURL = thumb.URL
+					response.ThumbnailWidth = thumb.Width
+					response.ThumbnailHeight = thumb.Height
+					break
+				}
+			}
+		}
+	} else if channel.LiveState == "live" {
+		response.IsLive = true
+		response.CacheAge = oEmbedLiveCacheAge
+		// A live frame of a restricted channel would leak what the embed
+		// withholds.
+		if embeddable(channel) {
+			response.ThumbnailURL = absoluteURL(base, "/api/channels/"+channel.ID+"/preview", nil)
+			response.ThumbnailWidth = embedDefaultWidth
+			response.ThumbnailHeight = embedDefaultHeight
+		}
+	}
+	response.HTML = fmt.Sprintf(`<iframe src="%s" width="%d" height="%d" title="%s" frameborder="0" allow="autoplay; fullscreen; picture-in-picture" allowfullscreen></iframe>`,
+		template.HTMLEscapeString(absoluteURL(base, embedPathPrefix+channel.ID, embedQuery)),
+		width,
+		height,
+		template.HTMLEscapeString(response.Title),
+	)
+
+	w.Header().Set("Cache-Control", fmt.Sprintf("public, max-age=%d", response.CacheAge))
+	WriteJSON(w, http.StatusOK, response)
+}
+
+// resolveEmbedTarget loads the channel and recording a target names. Only
+// published recordings resolve, and a recording must belong to the channel
+// when both are given.
+func (h *Handler) resolveEmbedTarget(target embedTarget) (models.Channel, *models.Recording, bool) {
+	var recording *models.Recording
+	channelID := target.channelID
+	if target.recordingID != "" {
+		loaded, ok := h.Store.GetRecording(target.recordingID)
+		if !ok || loaded.PublishedAt == nil {
+			return models.Channel{}, nil, false
+		}
+		if channelID != "" && loaded.ChannelID != channelID {
+			return models.Channel{}, nil, false
+		}
+		channelID = loaded.ChannelID
+		recording = &loaded
+	}
+	channel, ok := h.Store.GetChannel(channelID)
+	if !ok {
+		return models.Channel{}, nil, false
+	}
+	return channel, recording, true
+}
+
+// embedPage is the data rendered by embedTemplate. Source is empty when the
+// page shows a poster card instead of a player.
+type embedPage struct {
+	Title     string
+	Author    string
+	Source    string
+	Poster    string
+	Message   string
+	WatchURL  string
+	AvatarURL string
+}
+
+var embedTemplate = template.Must(template.New("embed").Parse(`<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<meta name="viewport" content="width=device-width, initial-scale=1">
+<meta name="robots" content="noindex">
+<title>{{.Title}} - BitRiver Live</title>
+<style>
+html, body { margin: 0; height: 100%; background: #000; color: #f5f5f5; font-family: system-ui, sans-serif; overflow: hidden; }
+video { width: 100%; height: 100%; display: block; background: #000; }
+.poster { height: 100%; display: flex; flex-direction: column; align-items: center; justify-content: center; gap: 0.75rem; text-align: center; padding: 1rem; box-sizing: border-box; }
+.poster img { width: 72px; height: 72px; border-radius: 50%; object-fit: cover; }
+.poster h1 { font-size: 1.1rem; margin: 0; }
+.poster p { margin: 0; color: #c8c8c8; }
+.poster a { color: #fff; background: #3b6cf6; padding: 0.5rem 1rem; border-radius: 4px; text-decoration: none; }
+</style>
+</head>
+<body>
+{{if .Source}}<video src="{{.Source}}"{{if .Poster}} poster="{{.Poster}}"{{end}} controls autoplay muted playsinline></video>
+{{else}}<div class="poster">
+{{if .AvatarURL}}<img src="{{.AvatarURL}}" alt="">
+{{end}}<h1>{{.Title}}</h1>
+{{if .Author}}<p>{{.Author}}</p>
+{{end}}<p>{{.Message}}</p>
+<a href="{{.WatchURL}}" target="_blank" rel="noopener">Watch on BitRiver Live</a>
+</div>
+{{end}}</body>
+</html>
+`))
+
+// Embed serves /embed/{channelID}, the chrome-less player third-party sites
+// frame, with ?recording={id} playing a published recording instead of the
+// live stream. Mature and restricted channels, offline channels, and
+// streams only offered over WebRTC render a poster linking to the channel
+// page instead of a player.
+func (h *Handler) Embed(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodHead)
+		return
+	}
+	channelID := strings.TrimPrefix(r.URL.Path, embedPathPrefix)
+	if channelID == "" || strings.Contains(channelID, "/") {
+		http.NotFound(w, r)
+		return
+	}
+	target := embedTarget{channelID: channelID, recordingID: strings.TrimSpace(r.URL.Query().Get("recording"))}
+	channel, recording, ok := h.resolveEmbedTarget(target)
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+	owner, _ := h.Store.GetUser(channel.OwnerID)
+	profile, _ := h.Store.GetProfile(channel.OwnerID)
+
+	page := embedPage{
+		Title:     channel.Title,
+		Author:    owner.DisplayName,
+		WatchURL:  viewerChannelPathPrefix + channel.ID,
+		AvatarURL: profile.AvatarURL,
+	}
+	switch {
+	case recording != nil:
+		page.Title = recording.Title
+		item := newVodItemResponse(*recording)
+		page.Source, page.Poster = item.PlaybackURL, item.ThumbnailURL
+		if page.Source == "" {
+			page.Message = "This recording is not available yet."
+		}
+	case channel.LiveState == "live":
+		if session, live := h.Store.CurrentStreamSession(channel.ID); live {
+			page.Source = session.PlaybackURL
+			if page.Source == "" && len(session.RenditionManifests) > 0 {
+				page.Source = session.RenditionManifests[0].ManifestURL
+			}
+			if models.LatencyModeForPlaybackURL(page.Source) == models.LatencyModeUltraLow {
+				// WebRTC playback needs the full player.
+				page.Source = ""
+				page.Message = "Watch this stream on BitRiver Live."
+			}
+			page.Poster = "/api/channels/" + channel.ID + "/preview"
+		}
+		if page.Source == "" && page.Message == "" {
+			page.Message = "This stream is starting."
+		}
+	default:
+		page.Message = "This channel is offline."
+	}
+	if !embeddable(channel) {
+		page.Source, page.Poster = "", ""
+		page.Message = "This channel can only be watched on BitRiver Live."
+	}
+	if page.Source == "" {
+		page.Poster = ""
+	}
+
+	var body bytes.Buffer
+	if err := embedTemplate.Execute(&body, page); err != nil {
+		WriteError(w, http.StatusInternalServerError, fmt.Errorf("render embed: %w", err))
+		return
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	// Live state and restrictions change, so the page is only briefly
+	// cacheable.
+	w.Header().Set("Cache-Control", "public, max-age=30")
+	w.WriteHeader(http.StatusOK)
+	if r.Method == http.MethodHead {
+		return
+	}
+	_, _ = w.Write(body.Bytes())
+}
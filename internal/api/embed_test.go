@@ -0,0 +1,292 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/auth"
+	"bitriver-live/internal/ingest"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+// newEmbedFixture returns a handler with a live channel and an offline
+// channel that has a published recording.
+func newEmbedFixture(t *testing.T) (*Handler, storage.Repository, models.Channel, models.Channel, models.Recording) {
+	t.Helper()
+	store, err := storage.NewStorage(filepath.Join(t.TempDir(), "store.json"), storage.WithIngestController(bootResultController{result: ingest.BootResult{
+		PlaybackURL: "https://cdn.example/live/master.m3u8",
+	}}), storage.WithIngestRetries(1, 0))
+	if err != nil {
+		t.Fatalf("NewStorage: %v", err)
+	}
+	handler := NewHandler(store, auth.NewSessionManager(24*time.Hour))
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Streamer", Email: "streamer@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	live, err := store.CreateChannel(owner.ID, "Speedrun Night", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel live: %v", err)
+	}
+	archive, err := store.CreateChannel(owner.ID, "Archive", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel archive: %v", err)
+	}
+	if _, err := store.StartStream(archive.ID, []string{"720p"}); err != nil {
+		t.Fatalf("StartStream archive: %v", err)
+	}
+	if _, err := store.StopStream(archive.ID, 3); err != nil {
+		t.Fatalf("StopStream archive: %v", err)
+	}
+	recordings, err := store.ListRecordings(archive.ID, true)
+	if err != nil || len(recordings) == 0 {
+		t.Fatalf("ListRecordings: %v (%d)", err, len(recordings))
+	}
+	recording, err := store.PublishRecording(recordings[0].ID)
+	if err != nil {
+		t.Fatalf("PublishRecording: %v", err)
+	}
+	if _, err := store.StartStream(live.ID, []string{"720p"}); err != nil {
+		t.Fatalf("StartStream live: %v", err)
+	}
+	live, _ = store.GetChannel(live.ID)
+	archive, _ = store.GetChannel(archive.ID)
+	return handler, store, live, archive, recording
+}
+
+func getOEmbed(t *testing.T, handler *Handler, target string, extra url.Values) (*httptest.ResponseRecorder, oEmbedResponse) {
+	t.Helper()
+	query := url.Values{"url": {target}}
+	for key, values := range extra {
+		query[key] = values
+	}
+	req := httptest.NewRequest(http.MethodGet, "https://live.example/api/oembed?"+query.Encode(), nil)
+	rec := httptest.NewRecorder()
+	handler.OEmbed(rec, req)
+	var payload oEmbedResponse
+	if rec.Code == http.StatusOK {
+		if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+			t.Fatalf("decode oembed: %v", err)
+		}
+	}
+	return rec, payload
+}
+
+func getEmbed(t *testing.T, handler *Handler, path string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "https://live.example"+path, nil)
+	rec := httptest.NewRecorder()
+	handler.Embed(rec, req)
+	return rec
+}
+
+func TestOEmbedLiveChannel(t *testing.T) {
+	handler, _, live, _, _ := newEmbedFixture(t)
+
+	rec, payload := getOEmbed(t, handler, "https://live.example/viewer/channels/"+live.ID, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if payload.Version != "1.0" || payload.Type != "video" || payload.ProviderName != "BitRiver Live" {
+		t.Fatalf("unexpected oembed envelope: %+v", payload)
+	}
+	if payload.Title != "Speedrun Night" || payload.AuthorName != "Streamer" {
+		t.Fatalf("unexpected title/author: %q / %q", payload.Title, payload.AuthorName)
+	}
+	if payload.AuthorURL != "https://live.example/viewer/channels/"+live.ID {
+		t.Fatalf("unexpected author url %q", payload.AuthorURL)
+	}
+	if !payload.IsLive {
+		t.Fatal("expected is_live for a live channel")
+	}
+	if payload.ThumbnailURL != "https://live.example/api/channels/"+live.ID+"/preview" || payload.ThumbnailWidth == 0 || payload.ThumbnailHeight == 0 {
+		t.Fatalf("expected live preview thumbnail, got %q %dx%d", payload.ThumbnailURL, payload.ThumbnailWidth, payload.ThumbnailHeight)
+	}
+	if !strings.Contains(payload.HTML, `<iframe src="https://live.example/embed/`+live.ID+`"`) {
+		t.Fatalf("expected iframe pointing at the embed page, got %s", payload.HTML)
+	}
+	if payload.Width != 640 || payload.Height != 360 {
+		t.Fatalf("expected default 640x360, got %dx%d", payload.Width, payload.Height)
+	}
+
+	_, sized := getOEmbed(t, handler, "https://live.example/viewer/channels/"+live.ID, url.Values{"maxwidth": {"320"}})
+	if sized.Width != 320 || sized.Height != 180 || !strings.Contains(sized.HTML, `width="320" height="180"`) {
+		t.Fatalf("expected maxwidth to scale the player to 320x180, got %dx%d: %s", sized.Width, sized.Height, sized.HTML)
+	}
+}
+
+func TestOEmbedOfflineChannel(t *testing.T) {
+	handler, _, _, archive, _ := newEmbedFixture(t)
+
+	rec, payload := getOEmbed(t, handler, "https://live.example/channels/"+archive.ID, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if payload.IsLive {
+		t.Fatal("expected offline channel not to be live")
+	}
+	if payload.ThumbnailURL != "" {
+		t.Fatalf("expected no live preview for an offline channel, got %q", payload.ThumbnailURL)
+	}
+	if payload.Title != "Archive" || !strings.Contains(payload.HTML, "/embed/"+archive.ID+`"`) {
+		t.Fatalf("unexpected offline payload: %+v", payload)
+	}
+}
+
+func TestOEmbedRecording(t *testing.T) {
+	handler, store, _, archive, recording := newEmbedFixture(t)
+
+	rec, payload := getOEmbed(t, handler, "https://live.example/viewer/recordings/"+recording.ID, nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if payload.Title != recording.Title || payload.IsLive {
+		t.Fatalf("unexpected recording payload: %+v", payload)
+	}
+	if payload.AuthorURL != "https://live.example/viewer/channels/"+archive.ID {
+		t.Fatalf("expected recording author url to name its channel, got %q", payload.AuthorURL)
+	}
+	if !strings.Contains(payload.HTML, "/embed/"+archive.ID+"?recording="+recording.ID) {
+		t.Fatalf("expected iframe to play the recording, got %s", payload.HTML)
+	}
+
+	if _, err := store.StartStream(archive.ID, []string{"720p"}); err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	if _, err := store.StopStream(archive.ID, 1); err != nil {
+		t.Fatalf("StopStream: %v", err)
+	}
+	recordings, err := store.ListRecordings(archive.ID, true)
+	if err != nil {
+		t.Fatalf("ListRecordings: %v", err)
+	}
+	for _, candidate := range recordings {
+		if candidate.PublishedAt != nil {
+			continue
+		}
+		if rec, _ := getOEmbed(t, handler, "https://live.example/recordings/"+candidate.ID, nil); rec.Code != http.StatusNotFound {
+			t.Fatalf("expected unpublished recording to 404, got %d", rec.Code)
+		}
+	}
+}
+
+func TestOEmbedRejectsUnknownURLsAndFormats(t *testing.T) {
+	handler, _, live, _, _ := newEmbedFixture(t)
+
+	for _, target := range []string{
+		"https://live.example/viewer/channels/missing",
+		"https://live.example/viewer/profile",
+		"ftp://live.example/channels/" + live.ID,
+	} {
+		if rec, _ := getOEmbed(t, handler, target, nil); rec.Code != http.StatusNotFound {
+			t.Fatalf("%s: expected 404, got %d", target, rec.Code)
+		}
+	}
+	if rec, _ := getOEmbed(t, handler, "https://live.example/channels/"+live.ID, url.Values{"format": {"xml"}}); rec.Code != http.StatusNotImplemented {
+		t.Fatalf("expected xml format to return 501, got %d", rec.Code)
+	}
+	rec, _ := getOEmbed(t, handler, "https://live.example/channels/"+live.ID, url.Values{"maxwidth": {"wide"}})
+	if rec.Code != http.StatusBadRequest || decodeAPIError(t, rec.Body.Bytes()).Error.Code != "validation_failed" {
+		t.Fatalf("expected invalid maxwidth to fail validation, got %d: %s", rec.Code, rec.Body.String())
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/oembed", nil)
+	missing := httptest.NewRecorder()
+	handler.OEmbed(missing, req)
+	if missing.Code != http.StatusBadRequest {
+		t.Fatalf("expected missing url to return 400, got %d", missing.Code)
+	}
+}
+
+func TestEmbedPlaysPublicChannelsAndRecordings(t *testing.T) {
+	handler, _, live, archive, recording := newEmbedFixture(t)
+
+	rec := getEmbed(t, handler, "/embed/"+live.ID)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); !strings.HasPrefix(ct, "text/html") {
+		t.Fatalf("expected html, got %q", ct)
+	}
+	body := rec.Body.String()
+	if !strings.Contains(body, `<video src="https://cdn.example/live/master.m3u8"`) {
+		t.Fatalf("expected live player, got %s", body)
+	}
+
+	rec = getEmbed(t, handler, "/embed/"+archive.ID)
+	if body := rec.Body.String(); strings.Contains(body, "<video") || !strings.Contains(body, "This channel is offline.") {
+		t.Fatalf("expected offline poster, got %s", body)
+	}
+
+	rec = getEmbed(t, handler, "/embed/"+archive.ID+"?recording="+recording.ID)
+	if body := rec.Body.String(); !strings.Contains(body, "<video") || !strings.Contains(body, recording.Title) {
+		t.Fatalf("expected recording player, got %s", body)
+	}
+
+	if rec := getEmbed(t, handler, "/embed/"+live.ID+"?recording="+recording.ID); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected recording from another channel to 404, got %d", rec.Code)
+	}
+	if rec := getEmbed(t, handler, "/embed/missing"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected unknown channel to 404, got %d", rec.Code)
+	}
+}
+
+func TestEmbedLeavesPublicLivePlaybackUnsigned(t *testing.T) {
+	handler, _, live, _, _ := newEmbedFixture(t)
+	signer, err := auth.NewPlaybackSigner(auth.PlaybackSignerConfig{Key: "playback-secret"})
+	if err != nil {
+		t.Fatalf("NewPlaybackSigner: %v", err)
+	}
+	handler.PlaybackSigner = signer
+
+	body := getEmbed(t, handler, "/embed/"+live.ID).Body.String()
+	if !strings.Contains(body, `<video src="https://cdn.example/live/master.m3u8"`) || strings.Contains(body, playbackTokenPathPrefix) {
+		t.Fatalf("expected an unsigned live player for a public channel, got %s", body)
+	}
+}
+
+func TestEmbedWithholdsRestrictedChannels(t *testing.T) {
+	mature := true
+	for _, tc := range []struct {
+		name   string
+		update storage.ChannelUpdate
+	}{
+		{name: "mature", update: storage.ChannelUpdate{MatureContent: &mature}},
+		{name: "followers-only", update: storage.ChannelUpdate{PlaybackRestriction: stringPtr(models.PlaybackRestrictionFollowers)}},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			handler, store, live, archive, recording := newEmbedFixture(t)
+			if _, err := store.UpdateChannel(live.ID, tc.update); err != nil {
+				t.Fatalf("UpdateChannel live: %v", err)
+			}
+			if _, err := store.UpdateChannel(archive.ID, tc.update); err != nil {
+				t.Fatalf("UpdateChannel archive: %v", err)
+			}
+
+			for _, path := range []string{"/embed/" + live.ID, "/embed/" + archive.ID + "?recording=" + recording.ID} {
+				rec := getEmbed(t, handler, path)
+				if rec.Code != http.StatusOK {
+					t.Fatalf("%s: expected 200, got %d", path, rec.Code)
+				}
+				body := rec.Body.String()
+				if strings.Contains(body, "<video") || strings.Contains(body, "cdn.example") {
+					t.Fatalf("%s: expected no player for a restricted channel, got %s", path, body)
+				}
+				if !strings.Contains(body, `href="/viewer/channels/`) || !strings.Contains(body, "can only be watched on BitRiver Live") {
+					t.Fatalf("%s: expected poster linking to the channel, got %s", path, body)
+				}
+			}
+
+			_, payload := getOEmbed(t, handler, "https://live.example/channels/"+live.ID, nil)
+			if payload.ThumbnailURL != "" {
+				t.Fatalf("expected restricted channel to omit the live thumbnail, got %q", payload.ThumbnailURL)
+			}
+		})
+	}
+}
@@ -0,0 +1,104 @@
+package api
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"net/http"
+	"strings"
+
+	"bitriver-live/internal/observability/metrics"
+)
+
+// conditionalGET opts a handler into ETags. Handlers pick sharedETag for
+// payloads that are identical for every viewer and viewerETag for payloads
+// that depend on who asks, so per-user content is never marked public.
+type conditionalGET struct {
+	// Endpoint labels the conditional response metric.
+	Endpoint string
+	// CacheControl is sent with both 200 and 304 responses.
+	CacheControl string
+}
+
+// sharedETag lets browsers and proxies reuse the payload for as long as the
+// read cache would serve it.
+func sharedETag(endpoint string) conditionalGET {
+	return conditionalGET{Endpoint: endpoint, CacheControl: "public, max-age=5"}
+}
+
+// viewerETag keeps the payload in the viewer's browser only and has it
+// revalidated on every poll, which is cheap once the ETag matches.
+func viewerETag(endpoint string) conditionalGET {
+	return conditionalGET{Endpoint: endpoint, CacheControl: "private, no-cache"}
+}
+
+// etagContent is implemented by payloads carrying fields, such as a
+// generation timestamp, that change on every build while the content does
+// not. ETags hash the returned value instead of the whole payload.
+type etagContent interface {
+	etagContent() interface{}
+}
+
+// encodeETagged encodes payload as a JSON response body and derives its
+// strong ETag.
+func encodeETagged(payload interface{}) (string, []byte, error) {
+	body, err := marshalResponse(payload)
+	if err != nil {
+		return "", nil, err
+	}
+	content := body
+	if versioned, ok := payload.(etagContent); ok {
+		if content, err = marshalResponse(versioned.etagContent()); err != nil {
+			return "", nil, err
+		}
+	}
+	return etagFor(content), append(body, '\n'), nil
+}
+
+func etagFor(content []byte) string {
+	sum := sha256.Sum256(content)
+	return `"` + hex.EncodeToString(sum[:16]) + `"`
+}
+
+// etagMatches reports whether an If-None-Match header lists etag. It uses
+// the weak comparison RFC 9110 prescribes for If-None-Match.
+func etagMatches(header, etag string) bool {
+	for _, candidate := range strings.Split(header, ",") {
+		candidate = strings.TrimSpace(candidate)
+		if candidate == "*" || strings.TrimPrefix(candidate, "W/") == etag {
+			return true
+		}
+	}
+	return false
+}
+
+// write sends body with its validators, or a bodiless 304 when the client
+// already holds the representation named by etag. Bodies are JSON unless
+// the caller set another Content-Type.
+func (c conditionalGET) write(w http.ResponseWriter, r *http.Request, etag string, body []byte) {
+	header := w.Header()
+	header.Set("ETag", etag)
+	header.Set("Cache-Control", c.CacheControl)
+	header.Set("Vary", "Authorization, Cookie")
+	if etagMatches(r.Header.Get("If-None-Match"), etag) {
+		metrics.Default().ObserveConditionalResponse(c.Endpoint, http.StatusNotModified)
+		w.WriteHeader(http.StatusNotModified)
+		return
+	}
+	metrics.Default().ObserveConditionalResponse(c.Endpoint, http.StatusOK)
+	if header.Get("Content-Type") == "" {
+		header.Set("Content-Type", "application/json")
+	}
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(body)
+}
+
+// writeETagJSON serves payload as a 200 JSON response with an ETag, or a 304
+// when the request's If-None-Match still matches.
+func (h *Handler) writeETagJSON(w http.ResponseWriter, r *http.Request, conditional conditionalGET, payload interface{}) {
+	etag, body, err := encodeETagged(payload)
+	if err != nil {
+		writeStorageError(w, http.StatusInternalServerError, err)
+		return
+	}
+	conditional.write(w, r, etag, body)
+}
@@ -0,0 +1,145 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"bitriver-live/internal/cache"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/observability/metrics"
+	"bitriver-live/internal/storage"
+)
+
+func TestDirectoryETags(t *testing.T) {
+	for _, cached := range []bool{false, true} {
+		handler, store := newTestHandler(t)
+		if cached {
+			handler.ReadCache = cache.New(cache.Config{})
+		}
+		owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+		if err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+		viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+		if err != nil {
+			t.Fatalf("CreateUser: %v", err)
+		}
+		channel, err := store.CreateChannel(owner.ID, "Original", "gaming", nil)
+		if err != nil {
+			t.Fatalf("CreateChannel: %v", err)
+		}
+
+		get := func(etag string) *httptest.ResponseRecorder {
+			req := httptest.NewRequest(http.MethodGet, "/api/directory", nil)
+			if etag != "" {
+				req.Header.Set("If-None-Match", etag)
+			}
+			rec := httptest.NewRecorder()
+			handler.Directory(rec, req)
+			return rec
+		}
+		label := metrics.ConditionalResponseLabel{Endpoint: "directory", Status: "304"}
+		before := metrics.Default().ConditionalResponseCounts()[label]
+
+		first := get("")
+		etag := first.Header().Get("ETag")
+		if first.Code != http.StatusOK || etag == "" || first.Header().Get("Cache-Control") != "public, max-age=5" {
+			t.Fatalf("cached=%v: expected 200 with an ETag, got %d %v", cached, first.Code, first.Header())
+		}
+		unchanged := get(etag)
+		if unchanged.Code != http.StatusNotModified || unchanged.Body.Len() != 0 || unchanged.Header().Get("ETag") != etag {
+			t.Fatalf("cached=%v: expected an empty 304, got %d: %q", cached, unchanged.Code, unchanged.Body.String())
+		}
+		if after := metrics.Default().ConditionalResponseCounts()[label]; after != before+1 {
+			t.Fatalf("cached=%v: expected one 304 counted, got %d", cached, after-before)
+		}
+
+		req := withUser(httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/follow", nil), viewer)
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		if rec.Code >= 300 {
+			t.Fatalf("follow: %d %s", rec.Code, rec.Body.String())
+		}
+		followed := get(etag)
+		if followed.Code != http.StatusOK || followed.Header().Get("ETag") == etag {
+			t.Fatalf("cached=%v: expected a new ETag after a follow, got %d", cached, followed.Code)
+		}
+
+		etag = followed.Header().Get("ETag")
+		req = withUser(httptest.NewRequest(http.MethodPatch, "/api/channels/"+channel.ID, strings.NewReader(`{"title":"Renamed"}`)), owner)
+		rec = httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("update channel: %d %s", rec.Code, rec.Body.String())
+		}
+		if renamed := get(etag); renamed.Code != http.StatusOK || !strings.Contains(renamed.Body.String(), "Renamed") {
+			t.Fatalf("cached=%v: expected the renamed channel after a title change, got %d", cached, renamed.Code)
+		}
+	}
+}
+
+func TestChannelETagsSeparateViewers(t *testing.T) {
+	handler, store := newTestHandler(t)
+	handler.ReadCache = cache.New(cache.Config{})
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Variants", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	if err := store.FollowChannel(viewer.ID, channel.ID); err != nil {
+		t.Fatalf("FollowChannel: %v", err)
+	}
+
+	get := func(path string, user *models.User, etag string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/channels/"+channel.ID+path, nil)
+		if user != nil {
+			req = withUser(req, *user)
+		}
+		if etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		}
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		if rec.Code != http.StatusOK && rec.Code != http.StatusNotModified {
+			t.Fatalf("GET %s: %d %s", path, rec.Code, rec.Body.String())
+		}
+		return rec
+	}
+
+	for _, path := range []string{"", "/playback"} {
+		anonymous := get(path, nil, "")
+		personal := get(path, &owner, "")
+		if path == "/playback" {
+			personal = get(path, &viewer, "")
+		}
+		anonETag, personalETag := anonymous.Header().Get("ETag"), personal.Header().Get("ETag")
+		if anonETag == "" || personalETag == "" || anonETag == personalETag {
+			t.Fatalf("%q: expected distinct ETags for anonymous and signed-in content, got %q and %q", path, anonETag, personalETag)
+		}
+		if personal.Header().Get("Cache-Control") != "private, no-cache" {
+			t.Fatalf("%q: expected signed-in content to stay private, got %q", path, personal.Header().Get("Cache-Control"))
+		}
+		if !strings.Contains(personal.Header().Get("Vary"), "Cookie") {
+			t.Fatalf("%q: expected responses to vary by credentials, got %v", path, personal.Header())
+		}
+		user := owner
+		if path == "/playback" {
+			user = viewer
+		}
+		if rec := get(path, &user, anonETag); rec.Code != http.StatusOK {
+			t.Fatalf("%q: expected the anonymous ETag not to validate signed-in content, got %d", path, rec.Code)
+		}
+		if rec := get(path, &user, personalETag); rec.Code != http.StatusNotModified {
+			t.Fatalf("%q: expected 304 for the viewer's own ETag, got %d", path, rec.Code)
+		}
+	}
+}
@@ -0,0 +1,306 @@
+package api
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"bitriver-live/internal/authz"
+	"bitriver-live/internal/featureflags"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+const (
+	// featureFlagsCookie holds a random identifier anonymous callers are
+	// bucketed by, so their flag assignment survives across visits. It
+	// carries no other data and is not signed: changing it only moves the
+	// caller to another bucket.
+	featureFlagsCookie = "bitriver_ff"
+	// featureFlagsCookieTTL keeps an anonymous caller's bucket for a year.
+	featureFlagsCookieTTL = 365 * 24 * time.Hour
+
+	featureFlagsContextKey contextKey = "featureFlags"
+)
+
+// FeatureFlags evaluates feature flags for one caller. The flags are loaded
+// on first use and the results kept for the rest of the request.
+type FeatureFlags struct {
+	store   storage.Repository
+	userID  string
+	subject string
+
+	once   sync.Once
+	values map[string]bool
+}
+
+// Enabled reports whether the flag is on for the caller. Unknown flags and
+// flags that cannot be loaded are off.
+func (f *FeatureFlags) Enabled(key string) bool {
+	return f.Values()[key]
+}
+
+// Values returns every flag's value for the caller, keyed by flag key.
+func (f *FeatureFlags) Values() map[string]bool {
+	f.once.Do(func() {
+		f.values = map[string]bool{}
+		if f.store == nil {
+			return
+		}
+		flags, err := f.store.ListFeatureFlags()
+		if err != nil {
+			return
+		}
+		f.values = featureflags.Evaluate(flags, f.userID, f.subject)
+	})
+	return f.values
+}
+
+// ContextWithFeatureFlags stores the caller's flag evaluator in ctx.
+func ContextWithFeatureFlags(ctx context.Context, flags *FeatureFlags) context.Context {
+	return context.WithValue(ctx, featureFlagsContextKey, flags)
+}
+
+// FeatureFlagsFromContext returns the evaluator stored by
+// ContextWithFeatureFlags, if any.
+func FeatureFlagsFromContext(ctx context.Context) (*FeatureFlags, bool) {
+	flags, ok := ctx.Value(featureFlagsContextKey).(*FeatureFlags)
+	return flags, ok && flags != nil
+}
+
+// newFeatureFlags builds the evaluator for the request's caller: signed-in
+// users bucket by user ID and anonymous callers by their flags cookie.
+func (h *Handler) newFeatureFlags(r *http.Request) *FeatureFlags {
+	flags := &FeatureFlags{store: h.Store}
+	if user, ok := UserFromContext(r.Context()); ok {
+		flags.userID = user.ID
+		flags.subject = user.ID
+	} else if cookie, err := r.Cookie(featureFlagsCookie); err == nil && validAnonymousFlagsID(cookie.Value) {
+		flags.subject = "anon:" + cookie.Value
+	}
+	return flags
+}
+
+// WithFeatureFlags returns r with the caller's flag evaluator in its
+// context. It must run after authentication so signed-in users are
+// recognised.
+func (h *Handler) WithFeatureFlags(r *http.Request) *http.Request {
+	return r.WithContext(ContextWithFeatureFlags(r.Context(), h.newFeatureFlags(r)))
+}
+
+// featureFlags returns the request's evaluator, building one when no
+// middleware attached it.
+func (h *Handler) featureFlags(r *http.Request) *FeatureFlags {
+	if flags, ok := FeatureFlagsFromContext(r.Context()); ok {
+		return flags
+	}
+	return h.newFeatureFlags(r)
+}
+
+// FeatureEnabled reports whether the flag is on for the request's caller.
+func (h *Handler) FeatureEnabled(r *http.Request, key string) bool {
+	return h.featureFlags(r).Enabled(key)
+}
+
+func validAnonymousFlagsID(value string) bool {
+	if len(value) != 32 {
+		return false
+	}
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
+
+// ensureAnonymousFlagsCookie gives an anonymous caller without one a flags
+// cookie and returns the request as if it had carried it.
+func (h *Handler) ensureAnonymousFlagsCookie(w http.ResponseWriter, r *http.Request) (*http.Request, error) {
+	if _, ok := UserFromContext(r.Context()); ok {
+		return r, nil
+	}
+	if cookie, err := r.Cookie(featureFlagsCookie); err == nil && validAnonymousFlagsID(cookie.Value) {
+		return r, nil
+	}
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return nil, fmt.Errorf("generate feature flags id: %w", err)
+	}
+	policy := h.sessionCookiePolicy()
+	cookie := &http.Cookie{
+		Name:     featureFlagsCookie,
+		Value:    hex.EncodeToString(buf),
+		Path:     "/",
+		Expires:  h.now().Add(featureFlagsCookieTTL),
+		MaxAge:   int(featureFlagsCookieTTL.Seconds()),
+		HttpOnly: true,
+		Secure:   policy.secure(r),
+		SameSite: policy.SameSite,
+	}
+	http.SetCookie(w, cookie)
+	r = r.Clone(r.Context())
+	r.AddCookie(cookie)
+	return r.WithContext(ContextWithFeatureFlags(r.Context(), h.newFeatureFlags(r))), nil
+}
+
+type configResponse struct {
+	// Features maps every feature flag key to whether it is on for the
+	// caller.
+	Features map[string]bool `json:"features"`
+}
+
+// Config serves GET /api/config, the caller's client configuration. It
+// carries the evaluated feature flags so the viewer and control centre can
+// branch on them. Anonymous callers without a flags cookie are given one so
+// their assignment stays stable.
+func (h *Handler) Config(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	r, err := h.ensureAnonymousFlagsCookie(w, r)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+	WriteJSON(w, http.StatusOK, configResponse{Features: h.featureFlags(r).Values()})
+}
+
+type featureFlagRequest struct {
+	Key            string   `json:"key"`
+	Description    string   `json:"description"`
+	Enabled        bool     `json:"enabled"`
+	RolloutPercent int      `json:"rolloutPercent"`
+	AllowUserIDs   []string `json:"allowUserIds"`
+	DenyUserIDs    []string `json:"denyUserIds"`
+}
+
+type featureFlagUpdateRequest struct {
+	Description    *string   `json:"description"`
+	Enabled        *bool     `json:"enabled"`
+	RolloutPercent *int      `json:"rolloutPercent"`
+	AllowUserIDs   *[]string `json:"allowUserIds"`
+	DenyUserIDs    *[]string `json:"denyUserIds"`
+}
+
+type featureFlagResponse struct {
+	Key            string   `json:"key"`
+	Description    string   `json:"description,omitempty"`
+	Enabled        bool     `json:"enabled"`
+	RolloutPercent int      `json:"rolloutPercent"`
+	AllowUserIDs   []string `json:"allowUserIds"`
+	DenyUserIDs    []string `json:"denyUserIds"`
+	CreatedAt      string   `json:"createdAt"`
+	UpdatedAt      string   `json:"updatedAt"`
+}
+
+func newFeatureFlagResponse(flag models.FeatureFlag) featureFlagResponse {
+	return featureFlagResponse{
+		Key:            flag.Key,
+		Description:    flag.Description,
+		Enabled:        flag.Enabled,
+		RolloutPercent: flag.RolloutPercent,
+		AllowUserIDs:   append([]string{}, flag.AllowUserIDs...),
+		DenyUserIDs:    append([]string{}, flag.DenyUserIDs...),
+		CreatedAt:      flag.CreatedAt.Format(time.RFC3339Nano),
+		UpdatedAt:      flag.UpdatedAt.Format(time.RFC3339Nano),
+	}
+}
+
+// AdminFeatureFlags serves /api/admin/feature-flags for holders of
+// platform.manage: GET lists the flags and POST adds one.
+func (h *Handler) AdminFeatureFlags(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodPost)
+		return
+	}
+	actor, ok := h.requirePermission(w, r, authz.PlatformManage)
+	if !ok {
+		return
+	}
+	if r.Method == http.MethodGet {
+		flags, err := h.Store.ListFeatureFlags()
+		if err != nil {
+			writeStorageError(w, http.StatusInternalServerError, err)
+			return
+		}
+		response := make([]featureFlagResponse, 0, len(flags))
+		for _, flag := range flags {
+			response = append(response, newFeatureFlagResponse(flag))
+		}
+		WriteJSON(w, http.StatusOK, response)
+		return
+	}
+	var req featureFlagRequest
+	if !DecodeAndValidate(w, r, &req) {
+		return
+	}
+	flag, err := h.Store.CreateFeatureFlag(storage.FeatureFlagParams{
+		Key:            req.Key,
+		Description:    req.Description,
+		Enabled:        req.Enabled,
+		RolloutPercent: req.RolloutPercent,
+		AllowUserIDs:   req.AllowUserIDs,
+		DenyUserIDs:    req.DenyUserIDs,
+	})
+	if err != nil {
+		writeStorageError(w, http.StatusBadRequest, err)
+		return
+	}
+	h.auditLogger().Info("audit", "action", "feature_flag.create", "actor_id", actor.ID, "flag", flag.Key, "enabled", flag.Enabled, "rollout_percent", flag.RolloutPercent)
+	WriteJSON(w, http.StatusCreated, newFeatureFlagResponse(flag))
+}
+
+// AdminFeatureFlagByKey serves /api/admin/feature-flags/{key} for holders
+// of platform.manage: GET reads a flag, PATCH changes it, and DELETE
+// removes it, which turns the feature off for everyone.
+func (h *Handler) AdminFeatureFlagByKey(w http.ResponseWriter, r *http.Request) {
+	actor, ok := h.requirePermission(w, r, authz.PlatformManage)
+	if !ok {
+		return
+	}
+	key := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/feature-flags/"), "/")
+	if key == "" || strings.Contains(key, "/") {
+		WriteError(w, http.StatusNotFound, fmt.Errorf("unknown feature flags path"))
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		flag, ok := h.Store.GetFeatureFlag(key)
+		if !ok {
+			WriteError(w, http.StatusNotFound, fmt.Errorf("feature flag %s not found", key))
+			return
+		}
+		WriteJSON(w, http.StatusOK, newFeatureFlagResponse(flag))
+	case http.MethodPatch:
+		var req featureFlagUpdateRequest
+		if !DecodeAndValidate(w, r, &req) {
+			return
+		}
+		flag, err := h.Store.UpdateFeatureFlag(key, storage.FeatureFlagUpdate{
+			Description:    req.Description,
+			Enabled:        req.Enabled,
+			RolloutPercent: req.RolloutPercent,
+			AllowUserIDs:   req.AllowUserIDs,
+			DenyUserIDs:    req.DenyUserIDs,
+		})
+		if err != nil {
+			writeStorageError(w, http.StatusBadRequest, err)
+			return
+		}
+		h.auditLogger().Info("audit", "action", "feature_flag.update", "actor_id", actor.ID, "flag", flag.Key, "enabled", flag.Enabled, "rollout_percent", flag.RolloutPercent)
+		WriteJSON(w, http.StatusOK, newFeatureFlagResponse(flag))
+	case http.MethodDelete:
+		if err := h.Store.DeleteFeatureFlag(key); err != nil {
+			writeStorageError(w, http.StatusBadRequest, err)
+			return
+		}
+		h.auditLogger().Info("audit", "action", "feature_flag.delete", "actor_id", actor.ID, "flag", key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodPatch, http.MethodDelete)
+	}
+}
@@ -0,0 +1,142 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+func TestAdminFeatureFlags(t *testing.T) {
+	handler, store := newTestHandler(t)
+	admin, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Admin", Email: "admin@example.com", Roles: []string{"admin"}})
+	if err != nil {
+		t.Fatalf("CreateUser admin: %v", err)
+	}
+
+	cases := []struct {
+		name   string
+		serve  http.HandlerFunc
+		method string
+		path   string
+		body   string
+		status int
+		code   string
+	}{
+		{"create", handler.AdminFeatureFlags, http.MethodPost, "/api/admin/feature-flags", `{"key":"new-player","description":"Low-latency player","enabled":true,"rolloutPercent":10}`, http.StatusCreated, ""},
+		{"duplicate", handler.AdminFeatureFlags, http.MethodPost, "/api/admin/feature-flags", `{"key":"new-player"}`, http.StatusConflict, "conflict"},
+		{"bad key", handler.AdminFeatureFlags, http.MethodPost, "/api/admin/feature-flags", `{"key":"New Player!"}`, http.StatusBadRequest, "validation_failed"},
+		{"bad rollout", handler.AdminFeatureFlags, http.MethodPost, "/api/admin/feature-flags", `{"key":"ranking","rolloutPercent":150}`, http.StatusBadRequest, "validation_failed"},
+		{"unknown field", handler.AdminFeatureFlags, http.MethodPost, "/api/admin/feature-flags", `{"key":"ranking","percent":10}`, http.StatusBadRequest, ""},
+		{"raise rollout", handler.AdminFeatureFlagByKey, http.MethodPatch, "/api/admin/feature-flags/new-player", `{"rolloutPercent":25,"denyUserIds":["tester"]}`, http.StatusOK, ""},
+		{"negative rollout", handler.AdminFeatureFlagByKey, http.MethodPatch, "/api/admin/feature-flags/new-player", `{"rolloutPercent":-5}`, http.StatusBadRequest, "validation_failed"},
+		{"update missing", handler.AdminFeatureFlagByKey, http.MethodPatch, "/api/admin/feature-flags/ghost", `{"enabled":true}`, http.StatusNotFound, "not_found"},
+		{"read", handler.AdminFeatureFlagByKey, http.MethodGet, "/api/admin/feature-flags/new-player", "", http.StatusOK, ""},
+		{"read missing", handler.AdminFeatureFlagByKey, http.MethodGet, "/api/admin/feature-flags/ghost", "", http.StatusNotFound, ""},
+		{"unknown path", handler.AdminFeatureFlagByKey, http.MethodGet, "/api/admin/feature-flags/new-player/users", "", http.StatusNotFound, ""},
+		{"list", handler.AdminFeatureFlags, http.MethodGet, "/api/admin/feature-flags", "", http.StatusOK, ""},
+	}
+	for _, tc := range cases {
+		rec := serveBadges(t, tc.serve, admin, tc.method, tc.path, tc.body)
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+		if tc.code != "" {
+			if got := decodeAPIError(t, rec.Body.Bytes()).Error.Code; got != tc.code {
+				t.Fatalf("%s: expected error code %q, got %q", tc.name, tc.code, got)
+			}
+		}
+	}
+
+	flag, ok := store.GetFeatureFlag("new-player")
+	if !ok || flag.RolloutPercent != 25 || flag.Description != "Low-latency player" || len(flag.DenyUserIDs) != 1 {
+		t.Fatalf("expected the patch to change only the rollout and deny list, got %+v", flag)
+	}
+	if rec := serveBadges(t, handler.AdminFeatureFlagByKey, admin, http.MethodDelete, "/api/admin/feature-flags/new-player", ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("delete: expected 204, got %d", rec.Code)
+	}
+	if rec := serveBadges(t, handler.AdminFeatureFlagByKey, admin, http.MethodDelete, "/api/admin/feature-flags/new-player", ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("delete again: expected 404, got %d", rec.Code)
+	}
+}
+
+func TestConfigExposesCallerFeatureFlags(t *testing.T) {
+	handler, store := newTestHandler(t)
+	allowed, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Allowed", Email: "allowed@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	denied, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Denied", Email: "denied@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if _, err := store.CreateFeatureFlag(storage.FeatureFlagParams{Key: "new-player", Enabled: true, AllowUserIDs: []string{allowed.ID}}); err != nil {
+		t.Fatalf("CreateFeatureFlag: %v", err)
+	}
+	if _, err := store.CreateFeatureFlag(storage.FeatureFlagParams{Key: "directory-ranking", Enabled: true, RolloutPercent: 100, DenyUserIDs: []string{denied.ID}}); err != nil {
+		t.Fatalf("CreateFeatureFlag: %v", err)
+	}
+	if _, err := store.CreateFeatureFlag(storage.FeatureFlagParams{Key: "half", Enabled: true, RolloutPercent: 50}); err != nil {
+		t.Fatalf("CreateFeatureFlag: %v", err)
+	}
+
+	config := func(user *models.User, cookie *http.Cookie) (configResponse, *httptest.ResponseRecorder) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/config", nil)
+		if user != nil {
+			req = withUser(req, *user)
+		}
+		if cookie != nil {
+			req.AddCookie(cookie)
+		}
+		req = handler.WithFeatureFlags(req)
+		rec := httptest.NewRecorder()
+		handler.Config(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("config: %d %s", rec.Code, rec.Body.String())
+		}
+		var payload configResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+			t.Fatalf("decode config: %v", err)
+		}
+		return payload, rec
+	}
+
+	payload, rec := config(&allowed, nil)
+	if !payload.Features["new-player"] || !payload.Features["directory-ranking"] || len(payload.Features) != 3 {
+		t.Fatalf("expected the allowed user to see both flags on, got %v", payload.Features)
+	}
+	if len(rec.Result().Cookies()) != 0 {
+		t.Fatal("expected signed-in users not to get a flags cookie")
+	}
+	if payload, _ := config(&denied, nil); payload.Features["new-player"] || payload.Features["directory-ranking"] {
+		t.Fatalf("expected the denied user to see both flags off, got %v", payload.Features)
+	}
+
+	anonymous, rec := config(nil, nil)
+	cookies := rec.Result().Cookies()
+	if len(cookies) != 1 || cookies[0].Name != featureFlagsCookie || !cookies[0].HttpOnly {
+		t.Fatalf("expected an anonymous caller to get a flags cookie, got %+v", cookies)
+	}
+	if anonymous.Features["new-player"] || !anonymous.Features["directory-ranking"] {
+		t.Fatalf("expected anonymous callers to get only the full rollout, got %v", anonymous.Features)
+	}
+	for i := 0; i < 5; i++ {
+		again, rec := config(nil, cookies[0])
+		if again.Features["half"] != anonymous.Features["half"] {
+			t.Fatal("expected the flags cookie to keep the anonymous caller's assignment")
+		}
+		if len(rec.Result().Cookies()) != 0 {
+			t.Fatal("expected a caller with a flags cookie not to get another")
+		}
+	}
+
+	// Handlers consult the same evaluator through the request context.
+	req := handler.WithFeatureFlags(withUser(httptest.NewRequest(http.MethodGet, "/api/directory", nil), allowed))
+	if !handler.FeatureEnabled(req, "new-player") || handler.FeatureEnabled(req, "missing") {
+		t.Fatal("expected FeatureEnabled to evaluate the caller's flags")
+	}
+}
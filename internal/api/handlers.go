@@ -2,18 +2,25 @@ package api
 
 import (
 	"context"
+	"fmt"
 	"log/slog"
+	"math/rand"
 	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"bitriver-live/internal/auth"
 	"bitriver-live/internal/auth/oauth"
+	"bitriver-live/internal/authz"
+	"bitriver-live/internal/cache"
 	"bitriver-live/internal/chat"
 	"bitriver-live/internal/ingest"
+	"bitriver-live/internal/mail"
 	"bitriver-live/internal/models"
-	"bitriver-live/internal/observability/metrics"
+	"bitriver-live/internal/observability/health"
+	"bitriver-live/internal/observability/logging"
 	"bitriver-live/internal/storage"
 )
 
@@ -35,14 +42,120 @@ type Handler struct {
 	uploadDirOnce       sync.Once
 	uploadDir           string
 	SessionCookiePolicy SessionCookiePolicy
+	srsViewersOnce      sync.Once
 	srsViewers          *srsViewerTracker
 	Logger              *slog.Logger
+	// AuditLogger records administrative changes that touch many resources
+	// at once. Nil falls back to Logger.
+	AuditLogger *slog.Logger
+	// Random drives randomized choices such as picking gift recipients.
+	// Tests inject a seeded source; nil falls back to a time-seeded one.
+	Random   *rand.Rand
+	randomMu sync.Mutex
+	// Now returns the current time. Tests inject a fixed clock; nil falls
+	// back to time.Now.
+	Now func() time.Time
+	// ReadCache fronts public directory and channel reads. Nil disables
+	// caching.
+	ReadCache *cache.Cache
+	// PlaybackSigner signs manifest URLs of channels with restricted
+	// playback. Nil leaves restricted channels unplayable.
+	PlaybackSigner *auth.PlaybackSigner
+	// TranscodeLimits are the platform caps a requested ladder must fit in;
+	// a channel's override replaces individual fields.
+	TranscodeLimits models.TranscodeLimits
+	// LadderProfiles supplies the bitrate of each named rendition when a
+	// requested ladder is checked against TranscodeLimits.
+	LadderProfiles []ingest.Rendition
+	// ComponentHealth tracks background workers. Nil reports none.
+	ComponentHealth *health.Registry
+	// ReadyRequiresComponents fails readiness when a background component
+	// is degraded or stalled instead of only reporting it.
+	ReadyRequiresComponents bool
+	previews                channelPreviewCache
+	statuses                channelStatusCache
+	// LiveClipLimit and LiveClipWindow cap how many live clips one viewer
+	// can cut from a channel per window. Zero values use 3 per minute.
+	LiveClipLimit  int
+	LiveClipWindow time.Duration
+	liveClips      windowLimiter
+	// ReportLimit and ReportWindow cap how many channel, stream, and
+	// recording reports one viewer can file per window. Zero values use 5
+	// per 10 minutes.
+	ReportLimit  int
+	ReportWindow time.Duration
+	reports      windowLimiter
+	// AnalyticsExportLimit and AnalyticsExportWindow cap how many analytics
+	// exports a channel can run per window. Zero values use 10 per hour.
+	AnalyticsExportLimit  int
+	AnalyticsExportWindow time.Duration
+	analyticsExports      windowLimiter
+	// RecordingPlaybackTTL is how long presigned recording playback URLs
+	// stay valid. Zero uses 15 minutes.
+	RecordingPlaybackTTL    time.Duration
+	recordingPlaybackGrants recordingPlaybackGrants
+	// KeyLeakSourceLimit and KeyLeakWindow flag a channel whose stream key
+	// is published from more than KeyLeakSourceLimit distinct networks
+	// (/24 for IPv4, /48 for IPv6) within KeyLeakWindow. Zero values use 3
+	// per 24 hours.
+	KeyLeakSourceLimit int
+	KeyLeakWindow      time.Duration
+	// SessionPeakInterval is the shortest gap between two writes of a live
+	// session's viewer peak to storage. Zero uses 30 seconds.
+	SessionPeakInterval time.Duration
+	sessionPeaks        sessionPeakWriter
+	// ProvisioningToken authorizes the identity-provider provisioning API.
+	// Empty disables it.
+	ProvisioningToken string
+	// IngestEventsToken authorizes the ingest server's media events. Empty
+	// disables them, and live channels are then reported as receiving
+	// media.
+	IngestEventsToken string
+	// CookieSigningKey signs cookies issued to anonymous viewers, such as
+	// their playback preferences. Each use derives its own subkey. Empty
+	// disables those cookies.
+	CookieSigningKey []byte
+	// Mail queues outgoing email such as security notices. Nil skips
+	// sending.
+	Mail *mail.Queue
+	// LegacyAPISunset is advertised in the Sunset header of responses that
+	// serve a deprecated shape under the unversioned /api/ prefix. Zero
+	// omits the header.
+	LegacyAPISunset time.Time
+	// IngestHealthPoller keeps the ingest health snapshot fresh and probes
+	// on demand when an administrator asks /healthz to refresh. Nil leaves
+	// the snapshot to whatever else records it.
+	IngestHealthPoller ingestHealthRefresher
+	ingestRefreshMu    sync.Mutex
+	ingestRefreshedAt  time.Time
+	// StreamRequestBudget bounds how long a stream start or stop request
+	// waits on ingest before answering 504. Zero uses a 25s default.
+	StreamRequestBudget time.Duration
+	// StatsRollupInterval is how often the platform statistics are
+	// recomputed. Rollups older than twice this are reported as stale.
+	// Zero uses a 15m default.
+	StatsRollupInterval time.Duration
+	// LogLevels controls the process's log levels for
+	// /api/admin/logging. Nil disables the endpoint.
+	LogLevels *logging.LevelController
 }
 
 type healthPinger interface {
 	Ping(context.Context) error
 }
 
+type ingestHealthRefresher interface {
+	Refresh(context.Context) error
+}
+
+// ingestHealthRefreshCooldown is how long /healthz?refresh=true waits
+// between forced probes of the ingest services.
+const ingestHealthRefreshCooldown = 10 * time.Second
+
+// defaultStreamRequestBudget is the StreamRequestBudget used when none is
+// configured. It stays under the 30s most clients give up after.
+const defaultStreamRequestBudget = 25 * time.Second
+
 // NewHandler wires the core API dependencies together, ensuring a session
 // manager is available by creating a default manager when none is provided.
 func NewHandler(store storage.Repository, sessions *auth.SessionManager) *Handler {
@@ -66,6 +179,24 @@ func (h *Handler) sessionManager() *auth.SessionManager {
 	return h.Sessions
 }
 
+// randomIntn returns a pseudo-random number in [0, n) from the handler's
+// random source.
+func (h *Handler) randomIntn(n int) int {
+	h.randomMu.Lock()
+	defer h.randomMu.Unlock()
+	if h.Random == nil {
+		h.Random = rand.New(rand.NewSource(time.Now().UnixNano()))
+	}
+	return h.Random.Intn(n)
+}
+
+func (h *Handler) now() time.Time {
+	if h.Now != nil {
+		return h.Now()
+	}
+	return time.Now()
+}
+
 func (h *Handler) logger() *slog.Logger {
 	if h.Logger == nil {
 		h.Logger = slog.Default()
@@ -73,20 +204,53 @@ func (h *Handler) logger() *slog.Logger {
 	return h.Logger
 }
 
+func (h *Handler) auditLogger() *slog.Logger {
+	if h.AuditLogger != nil {
+		return h.AuditLogger
+	}
+	return h.logger()
+}
+
+// store returns the repository to read from while serving r. Reads that
+// tolerate replication lag may be served by a Postgres read replica unless
+// readYourWrites marked r.
+func (h *Handler) store(r *http.Request) storage.Repository {
+	return storage.RepositoryForContext(r.Context(), h.Store)
+}
+
+// readYourWrites marks r so reads made through h.store(r) come from the
+// primary datastore and see what the request has written.
+func readYourWrites(r *http.Request) *http.Request {
+	return r.WithContext(storage.ContextWithPrimaryReads(r.Context()))
+}
+
 func (h *Handler) srsTracker() *srsViewerTracker {
-	if h.srsViewers == nil {
+	h.srsViewersOnce.Do(func() {
 		h.srsViewers = newSRSViewerTracker()
-	}
+	})
 	return h.srsViewers
 }
 
+// Health reports core dependencies along with the ingest services. Ingest
+// health comes from the snapshot the background poller records, so a hung
+// upstream never slows this endpoint down; the response says how old the
+// snapshot is. Administrators can add ?refresh=true to probe immediately.
 func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
 	ctx := r.Context()
 
+	if refresh, _ := strconv.ParseBool(r.URL.Query().Get("refresh")); refresh {
+		if !h.refreshIngestHealth(w, r) {
+			return
+		}
+	}
+
 	components, overallStatus, statusCode := h.componentHealth(ctx)
 	checks := []ingest.HealthStatus{}
+	var checkedAt time.Time
 	if h.Store != nil {
-		checks = h.Store.IngestHealth(ctx)
+		if last, at := h.Store.LastIngestHealth(); last != nil {
+			checks, checkedAt = last, at
+		}
 	}
 
 	for _, check := range checks {
@@ -103,12 +267,60 @@ func (h *Handler) Health(w http.ResponseWriter, r *http.Request) {
 		"services":   checks,
 		"components": components,
 	}
-	for _, check := range checks {
-		metrics.SetIngestHealth(check.Component, check.Status)
+	if !checkedAt.IsZero() {
+		age := h.now().Sub(checkedAt)
+		if age < 0 {
+			age = 0
+		}
+		payload["servicesCheckedAt"] = checkedAt.UTC().Format(time.RFC3339Nano)
+		payload["servicesAgeSeconds"] = int(age / time.Second)
 	}
 	WriteJSON(w, statusCode, payload)
 }
 
+// refreshIngestHealth runs a forced ingest health poll for an administrator,
+// at most once per ingestHealthRefreshCooldown. It writes the error response
+// and returns false when the refresh is refused.
+func (h *Handler) refreshIngestHealth(w http.ResponseWriter, r *http.Request) bool {
+	// /healthz sits outside the authenticated API, so the session is
+	// checked here.
+	user, ok := UserFromContext(r.Context())
+	if !ok {
+		authenticated, _, err := h.AuthenticateSession(r)
+		if err != nil {
+			WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+			return false
+		}
+		user = authenticated
+	}
+	if !authz.Has(user, authz.PlatformManage) {
+		WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
+		return false
+	}
+	if h.IngestHealthPoller == nil {
+		WriteRequestError(w, RequestError{Status: http.StatusServiceUnavailable, CodeVal: "ingest_health_poller_unavailable", Message: "ingest health polling is not running"})
+		return false
+	}
+
+	now := h.now()
+	h.ingestRefreshMu.Lock()
+	if wait := h.ingestRefreshedAt.Add(ingestHealthRefreshCooldown).Sub(now); !h.ingestRefreshedAt.IsZero() && wait > 0 {
+		h.ingestRefreshMu.Unlock()
+		seconds := int((wait + time.Second - 1) / time.Second)
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		WriteRequestError(w, RequestError{Status: http.StatusTooManyRequests, CodeVal: "rate_limited", Message: fmt.Sprintf("ingest health can be refreshed once every %s", ingestHealthRefreshCooldown)})
+		return false
+	}
+	h.ingestRefreshedAt = now
+	h.ingestRefreshMu.Unlock()
+
+	if err := h.IngestHealthPoller.Refresh(r.Context()); err != nil {
+		WriteError(w, http.StatusServiceUnavailable, fmt.Errorf("refresh ingest health: %w", err))
+		return false
+	}
+	return true
+}
+
 // Ready reports the status of core API dependencies without considering ingest
 // services so load balancers can gate traffic on database and session readiness
 // alone.
@@ -118,6 +330,14 @@ func (h *Handler) Ready(w http.ResponseWriter, r *http.Request) {
 		"status":     overallStatus,
 		"components": components,
 	}
+	if h.ComponentHealth != nil {
+		background, healthy := h.backgroundComponents()
+		payload["background"] = background
+		if !healthy && h.ReadyRequiresComponents {
+			payload["status"] = "degraded"
+			statusCode = http.StatusServiceUnavailable
+		}
+	}
 	WriteJSON(w, statusCode, payload)
 }
 
@@ -129,10 +349,24 @@ type sessionResponse struct {
 	Renditions         []string                    `json:"renditions"`
 	PeakConcurrent     int                         `json:"peakConcurrent"`
 	OriginURL          string                      `json:"originUrl,omitempty"`
+	PublishSource      string                      `json:"publishSource,omitempty"`
 	PlaybackURL        string                      `json:"playbackUrl,omitempty"`
+	PlaybackEndpoints  []playbackEndpointResponse  `json:"playbackEndpoints,omitempty"`
 	IngestEndpoints    []string                    `json:"ingestEndpoints,omitempty"`
 	IngestJobIDs       []string                    `json:"ingestJobIds,omitempty"`
 	RenditionManifests []renditionManifestResponse `json:"renditionManifests,omitempty"`
+	Settings           *sessionSettingsResponse    `json:"settings,omitempty"`
+}
+
+type sessionSettingsResponse struct {
+	RequestedRenditions []string                  `json:"requestedRenditions"`
+	Ladder              []ladderRenditionResponse `json:"ladder"`
+	LatencyMode         string                    `json:"latencyMode,omitempty"`
+}
+
+type ladderRenditionResponse struct {
+	Name    string `json:"name"`
+	Bitrate int    `json:"bitrate,omitempty"`
 }
 
 func newSessionResponse(session models.StreamSession) sessionResponse {
@@ -147,12 +381,14 @@ func newSessionResponse(session models.StreamSession) sessionResponse {
 		ended := session.EndedAt.Format(time.RFC3339Nano)
 		resp.EndedAt = &ended
 	}
+	resp.PublishSource = session.PublishSource
 	if session.OriginURL != "" {
 		resp.OriginURL = session.OriginURL
 	}
 	if session.PlaybackURL != "" {
 		resp.PlaybackURL = session.PlaybackURL
 	}
+	resp.PlaybackEndpoints = newPlaybackEndpointResponses(session.PlaybackEndpoints)
 	if len(session.IngestEndpoints) > 0 {
 		resp.IngestEndpoints = append([]string{}, session.IngestEndpoints...)
 	}
@@ -170,5 +406,16 @@ func newSessionResponse(session models.StreamSession) sessionResponse {
 		}
 		resp.RenditionManifests = manifests
 	}
+	if session.Settings != nil {
+		settings := &sessionSettingsResponse{
+			RequestedRenditions: append([]string{}, session.Settings.RequestedRenditions...),
+			Ladder:              make([]ladderRenditionResponse, 0, len(session.Settings.Ladder)),
+			LatencyMode:         session.Settings.LatencyMode,
+		}
+		for _, rung := range session.Settings.Ladder {
+			settings.Ladder = append(settings.Ladder, ladderRenditionResponse{Name: rung.Name, Bitrate: rung.Bitrate})
+		}
+		resp.Settings = settings
+	}
 	return resp
 }
@@ -8,6 +8,7 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math/rand"
 	"net/http"
 	"net/http/httptest"
 	"path/filepath"
@@ -18,9 +19,11 @@ import (
 
 	"bitriver-live/internal/auth"
 	"bitriver-live/internal/auth/oauth"
+	"bitriver-live/internal/cache"
 	"bitriver-live/internal/chat"
 	"bitriver-live/internal/ingest"
 	"bitriver-live/internal/models"
+	"bitriver-live/internal/observability/metrics"
 	"bitriver-live/internal/storage"
 )
 
@@ -92,6 +95,56 @@ func (r ingestUnavailableRepo) StopStream(channelID string, peakConcurrent int)
 	return models.StreamSession{}, storage.ErrIngestControllerUnavailable
 }
 
+func (r ingestUnavailableRepo) StartStreamContext(ctx context.Context, channelID string, renditions []string) (models.StreamSession, error) {
+	return models.StreamSession{}, storage.ErrIngestControllerUnavailable
+}
+
+func (r ingestUnavailableRepo) StopStreamContext(ctx context.Context, channelID string, peakConcurrent int) (models.StreamSession, error) {
+	return models.StreamSession{}, storage.ErrIngestControllerUnavailable
+}
+
+// previewRepository serves a fixed preview frame and counts captures.
+type previewRepository struct {
+	storage.Repository
+	frame    ingest.Frame
+	captures int
+}
+
+func (r *previewRepository) ChannelPreview(channelID string) (ingest.Frame, error) {
+	r.captures++
+	if len(r.frame.Data) == 0 {
+		return ingest.Frame{}, ingest.ErrFrameUnavailable
+	}
+	return r.frame, nil
+}
+
+// chatAuthorCountingRepository counts author lookups and appends a message
+// from a user deleted after the page was read.
+type chatAuthorCountingRepository struct {
+	storage.Repository
+	deletedUserID string
+	authorCalls   int
+	userCalls     int
+}
+
+func (r *chatAuthorCountingRepository) ListChatMessages(channelID string, limit int) ([]models.ChatMessage, error) {
+	messages, err := r.Repository.ListChatMessages(channelID, limit)
+	if err != nil {
+		return nil, err
+	}
+	return append(messages, models.ChatMessage{ID: "orphan", ChannelID: channelID, UserID: r.deletedUserID, Content: "bye", CreatedAt: time.Now().UTC()}), nil
+}
+
+func (r *chatAuthorCountingRepository) ChatAuthors(channelID string, userIDs []string) (map[string]chat.AuthorInfo, error) {
+	r.authorCalls++
+	return r.Repository.ChatAuthors(channelID, userIDs)
+}
+
+func (r *chatAuthorCountingRepository) GetUser(id string) (models.User, bool) {
+	r.userCalls++
+	return r.Repository.GetUser(id)
+}
+
 func withUser(req *http.Request, user models.User) *http.Request {
 	return req.WithContext(ContextWithUser(req.Context(), user))
 }
@@ -107,11 +160,13 @@ type oauthStub struct {
 	lastBegin      struct {
 		provider string
 		returnTo string
+		binding  oauth.Binding
 	}
 	lastComplete struct {
 		provider string
 		state    string
 		code     string
+		binding  oauth.Binding
 	}
 	lastCancel string
 }
@@ -121,9 +176,12 @@ type profileRepositoryWithOrphan struct {
 	orphan models.Profile
 }
 
-func (r profileRepositoryWithOrphan) ListProfiles() []models.Profile {
-	profiles := r.Repository.ListProfiles()
-	return append(profiles, r.orphan)
+func (r profileRepositoryWithOrphan) ListProfiles() ([]models.Profile, error) {
+	profiles, err := r.Repository.ListProfiles()
+	if err != nil {
+		return nil, err
+	}
+	return append(profiles, r.orphan), nil
 }
 
 func (s *oauthStub) Providers() []oauth.ProviderInfo {
@@ -133,26 +191,28 @@ func (s *oauthStub) Providers() []oauth.ProviderInfo {
 	return []oauth.ProviderInfo{}
 }
 
-func (s *oauthStub) Begin(provider, returnTo string) (oauth.BeginResult, error) {
+func (s *oauthStub) Begin(provider, returnTo string, binding oauth.Binding) (oauth.BeginResult, error) {
 	s.lastBegin.provider = provider
 	s.lastBegin.returnTo = returnTo
+	s.lastBegin.binding = binding
 	if s.beginError != nil {
 		return oauth.BeginResult{}, s.beginError
 	}
 	return s.beginResult, nil
 }
 
-func (s *oauthStub) Complete(provider, state, code string) (oauth.Completion, error) {
+func (s *oauthStub) Complete(provider, state, code string, binding oauth.Binding) (oauth.Completion, error) {
 	s.lastComplete.provider = provider
 	s.lastComplete.state = state
 	s.lastComplete.code = code
+	s.lastComplete.binding = binding
 	if s.completeError != nil {
 		return oauth.Completion{}, s.completeError
 	}
 	return s.completeResult, nil
 }
 
-func (s *oauthStub) Cancel(state string) (string, error) {
+func (s *oauthStub) Cancel(state string, binding oauth.Binding) (string, error) {
 	s.lastCancel = state
 	if s.cancelError != nil {
 		return "", s.cancelError
@@ -277,6 +337,81 @@ func TestProfilesList(t *testing.T) {
 	}
 }
 
+func TestProfilesListPaginates(t *testing.T) {
+	handler, store := newTestHandler(t)
+
+	admin, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Admin", Email: "admin@example.com", Roles: []string{"admin"}})
+	if err != nil {
+		t.Fatalf("CreateUser admin: %v", err)
+	}
+	bio := "streams"
+	for _, params := range []storage.CreateUserParams{
+		{DisplayName: "Zed", Email: "zed@corp.test", Roles: []string{"creator"}},
+		{DisplayName: "Amy", Email: "amy@corp.test", Roles: []string{"creator"}},
+		{DisplayName: "Corporal", Email: "corporal@example.com"},
+	} {
+		user, err := store.CreateUser(params)
+		if err != nil {
+			t.Fatalf("CreateUser %s: %v", params.Email, err)
+		}
+		if _, err := store.UpsertProfile(user.ID, storage.ProfileUpdate{Bio: &bio}); err != nil {
+			t.Fatalf("UpsertProfile %s: %v", params.Email, err)
+		}
+	}
+
+	type profilesPage struct {
+		Items []profileViewResponse `json:"items"`
+		Total int                   `json:"total"`
+		Page  int                   `json:"page"`
+	}
+	list := func(query string, user *models.User) profilesPage {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/profiles"+query, nil)
+		if user != nil {
+			req = withUser(req, *user)
+		}
+		rec := httptest.NewRecorder()
+		handler.Profiles(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200 for %s, got %d", query, rec.Code)
+		}
+		var page profilesPage
+		if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+			t.Fatalf("decode page for %s: %v", query, err)
+		}
+		return page
+	}
+	names := func(page profilesPage) string {
+		got := make([]string, 0, len(page.Items))
+		for _, item := range page.Items {
+			got = append(got, item.DisplayName)
+		}
+		return strings.Join(got, ",")
+	}
+
+	if page := list("?role=creator&sort=display_name", nil); page.Total != 2 || names(page) != "Amy,Zed" {
+		t.Fatalf("expected creators sorted by name, got total=%d names=%s", page.Total, names(page))
+	}
+	if page := list("?sort=-display_name&perPage=1&page=2", nil); page.Total != 3 || page.Page != 2 || names(page) != "Corporal" {
+		t.Fatalf("expected second page to hold Corporal, got total=%d page=%d names=%s", page.Total, page.Page, names(page))
+	}
+	// Anonymous callers only search display names so the public listing
+	// cannot be used to probe email addresses.
+	if page := list("?q=corp", nil); page.Total != 1 || names(page) != "Corporal" {
+		t.Fatalf("expected public search to match names only, got total=%d names=%s", page.Total, names(page))
+	}
+	if page := list("?q=corp&sort=display_name", &admin); page.Total != 3 || names(page) != "Amy,Corporal,Zed" {
+		t.Fatalf("expected admin search to include emails, got total=%d names=%s", page.Total, names(page))
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/profiles?sort=bio", nil)
+	rec := httptest.NewRecorder()
+	handler.Profiles(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unsupported sort, got %d", rec.Code)
+	}
+}
+
 func TestUsersEndpointCreatesAndListsUsers(t *testing.T) {
 	handler, store := newTestHandler(t)
 
@@ -331,6 +466,91 @@ func TestUsersEndpointCreatesAndListsUsers(t *testing.T) {
 	}
 }
 
+func TestUsersEndpointPaginates(t *testing.T) {
+	handler, store := newTestHandler(t)
+
+	admin, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Admin", Email: "admin@example.com", Roles: []string{"admin"}})
+	if err != nil {
+		t.Fatalf("CreateUser admin: %v", err)
+	}
+	for _, params := range []storage.CreateUserParams{
+		{DisplayName: "Carol", Email: "carol@corp.test", Roles: []string{"creator"}},
+		{DisplayName: "bob", Email: "bob@corp.test", Roles: []string{"creator"}},
+		{DisplayName: "Dave", Email: "dave@corp.test"},
+	} {
+		if _, err := store.CreateUser(params); err != nil {
+			t.Fatalf("CreateUser %s: %v", params.Email, err)
+		}
+	}
+
+	list := func(query string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/users"+query, nil)
+		req = withUser(req, admin)
+		rec := httptest.NewRecorder()
+		handler.Users(rec, req)
+		return rec
+	}
+
+	rec := list("")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected legacy list 200, got %d", rec.Code)
+	}
+	var legacy []userResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &legacy); err != nil {
+		t.Fatalf("expected bare array without pagination params: %v", err)
+	}
+	if len(legacy) != 4 {
+		t.Fatalf("expected 4 users in legacy list, got %d", len(legacy))
+	}
+
+	type usersPage struct {
+		Items   []userResponse `json:"items"`
+		Total   int            `json:"total"`
+		Page    int            `json:"page"`
+		PerPage int            `json:"perPage"`
+	}
+	cases := []struct {
+		name      string
+		query     string
+		wantEmail []string
+		wantTotal int
+		wantPage  int
+	}{
+		{name: "search", query: "?q=corp", wantEmail: []string{"carol@corp.test", "bob@corp.test", "dave@corp.test"}, wantTotal: 3, wantPage: 1},
+		{name: "search and role sorted", query: "?q=corp&role=creator&sort=display_name", wantEmail: []string{"bob@corp.test", "carol@corp.test"}, wantTotal: 2, wantPage: 1},
+		{name: "descending page two", query: "?sort=-email&page=2&perPage=2", wantEmail: []string{"bob@corp.test", "admin@example.com"}, wantTotal: 4, wantPage: 2},
+		{name: "page only", query: "?page=1", wantEmail: []string{"admin@example.com", "carol@corp.test", "bob@corp.test", "dave@corp.test"}, wantTotal: 4, wantPage: 1},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			rec := list(tc.query)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+			}
+			var page usersPage
+			if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+				t.Fatalf("decode page: %v", err)
+			}
+			if page.Total != tc.wantTotal || page.Page != tc.wantPage {
+				t.Fatalf("expected total=%d page=%d, got total=%d page=%d", tc.wantTotal, tc.wantPage, page.Total, page.Page)
+			}
+			got := make([]string, 0, len(page.Items))
+			for _, item := range page.Items {
+				got = append(got, item.Email)
+			}
+			if strings.Join(got, ",") != strings.Join(tc.wantEmail, ",") {
+				t.Fatalf("expected %v, got %v", tc.wantEmail, got)
+			}
+		})
+	}
+
+	for _, query := range []string{"?sort=password", "?page=0", "?perPage=500", "?perPage=abc"} {
+		if rec := list(query); rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %s, got %d", query, rec.Code)
+		}
+	}
+}
+
 func TestAuthorizationEnforced(t *testing.T) {
 	handler, store := newTestHandler(t)
 
@@ -527,6 +747,63 @@ func TestUserByID(t *testing.T) {
 				}
 			},
 		},
+		{
+			name:   "admin deactivates user",
+			method: http.MethodPatch,
+			setup: func(t *testing.T, store *storage.Storage) (models.User, models.User, []byte) {
+				admin, err := store.CreateUser(storage.CreateUserParams{
+					DisplayName: "Admin",
+					Email:       "deactivate-admin@example.com",
+					Roles:       []string{"admin"},
+				})
+				if err != nil {
+					t.Fatalf("CreateUser admin: %v", err)
+				}
+				target, err := store.CreateUser(storage.CreateUserParams{
+					DisplayName: "Spammer",
+					Email:       "spammer@example.com",
+				})
+				if err != nil {
+					t.Fatalf("CreateUser target: %v", err)
+				}
+				return admin, target, []byte(`{"active":false}`)
+			},
+			wantStatus: http.StatusOK,
+			assert: func(t *testing.T, rec *httptest.ResponseRecorder, store *storage.Storage, target models.User) {
+				var resp userResponse
+				if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+					t.Fatalf("decode response: %v", err)
+				}
+				if resp.DeactivatedAt == nil {
+					t.Fatal("expected the response to carry deactivatedAt")
+				}
+				persisted, ok := store.GetUser(target.ID)
+				if !ok || !persisted.Deactivated() {
+					t.Fatalf("expected user %s to be deactivated", target.ID)
+				}
+			},
+		},
+		{
+			name:   "admin cannot deactivate themselves",
+			method: http.MethodPatch,
+			setup: func(t *testing.T, store *storage.Storage) (models.User, models.User, []byte) {
+				admin, err := store.CreateUser(storage.CreateUserParams{
+					DisplayName: "Admin",
+					Email:       "self-admin@example.com",
+					Roles:       []string{"admin"},
+				})
+				if err != nil {
+					t.Fatalf("CreateUser admin: %v", err)
+				}
+				return admin, admin, []byte(`{"active":false}`)
+			},
+			wantStatus: http.StatusBadRequest,
+			assert: func(t *testing.T, rec *httptest.ResponseRecorder, store *storage.Storage, target models.User) {
+				if persisted, _ := store.GetUser(target.ID); persisted.Deactivated() {
+					t.Fatal("expected the admin to stay active")
+				}
+			},
+		},
 		{
 			name:   "admin deletes user",
 			method: http.MethodDelete,
@@ -917,6 +1194,92 @@ func TestDirectoryFiltersChannelsByQuery(t *testing.T) {
 	}
 }
 
+func TestDirectoryFiltersByLanguageAndContentWarnings(t *testing.T) {
+	handler, store := newTestHandler(t)
+	creator, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Host", Email: "host@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("create creator: %v", err)
+	}
+	poker, err := store.CreateChannel(creator.ID, "Poker Night", "gaming", nil)
+	if err != nil {
+		t.Fatalf("create poker: %v", err)
+	}
+	cooking, err := store.CreateChannel(creator.ID, "Cocina", "food", nil)
+	if err != nil {
+		t.Fatalf("create cooking: %v", err)
+	}
+	plain, err := store.CreateChannel(creator.ID, "Unlabelled", "chat", nil)
+	if err != nil {
+		t.Fatalf("create plain: %v", err)
+	}
+
+	patch := func(channelID, body string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(http.MethodPatch, "/api/channels/"+channelID, strings.NewReader(body)), creator)
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		return rec
+	}
+	rec := patch(poker.ID, `{"language":"en-gb","contentWarnings":["gambling","strong_language"]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var updated channelResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &updated); err != nil {
+		t.Fatalf("decode channel: %v", err)
+	}
+	if updated.Language != "en-GB" || strings.Join(updated.ContentWarnings, ",") != "gambling,strong_language" {
+		t.Fatalf("expected the metadata in the channel response, got %q %v", updated.Language, updated.ContentWarnings)
+	}
+	if rec := patch(cooking.ID, `{"language":"es"}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	for _, body := range []string{`{"language":"elvish"}`, `{"contentWarnings":["spoilers"]}`} {
+		if rec := patch(plain.ID, body); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d: %s", body, rec.Code, rec.Body.String())
+		}
+	}
+
+	cases := []struct {
+		query   string
+		status  int
+		wantIDs []string
+	}{
+		{"", http.StatusOK, []string{poker.ID, cooking.ID, plain.ID}},
+		{"?language=es", http.StatusOK, []string{cooking.ID}},
+		{"?language=EN-GB&excludeWarnings=violence", http.StatusOK, []string{poker.ID}},
+		{"?excludeWarnings=gambling", http.StatusOK, []string{cooking.ID, plain.ID}},
+		{"?excludeWarnings=violence,strong_language", http.StatusOK, []string{cooking.ID, plain.ID}},
+		{"?excludeWarnings=violence&excludeWarnings=gambling&q=poker", http.StatusOK, []string{}},
+		{"?language=de", http.StatusOK, []string{}},
+		{"?language=elvish", http.StatusBadRequest, nil},
+		{"?excludeWarnings=spoilers", http.StatusBadRequest, nil},
+	}
+	for _, tc := range cases {
+		rec := httptest.NewRecorder()
+		handler.Directory(rec, httptest.NewRequest(http.MethodGet, "/api/directory"+tc.query, nil))
+		if rec.Code != tc.status {
+			t.Fatalf("%q: expected %d, got %d: %s", tc.query, tc.status, rec.Code, rec.Body.String())
+		}
+		if tc.status != http.StatusOK {
+			continue
+		}
+		var resp directoryResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		ids := make([]string, 0, len(resp.Channels))
+		for _, entry := range resp.Channels {
+			ids = append(ids, entry.Channel.ID)
+		}
+		if strings.Join(ids, ",") != strings.Join(tc.wantIDs, ",") {
+			t.Fatalf("%q: expected %v, got %v", tc.query, tc.wantIDs, ids)
+		}
+		if tc.query == "?language=es" && resp.Channels[0].Channel.Language != "es" {
+			t.Fatalf("expected the directory entry to carry the language, got %+v", resp.Channels[0].Channel)
+		}
+	}
+}
+
 func TestDirectoryFollowingRequiresAuthentication(t *testing.T) {
 	handler, _ := newTestHandler(t)
 
@@ -1249,84 +1612,254 @@ func TestDirectoryLiveRejectsNonGet(t *testing.T) {
 	}
 }
 
-func TestDirectoryFollowingListsLiveFollowedChannels(t *testing.T) {
+func TestDirectoryReadCacheInvalidatedByWrites(t *testing.T) {
 	handler, store := newTestHandler(t)
+	handler.ReadCache = cache.New(cache.Config{TTL: time.Hour, Metrics: metrics.New()})
 
+	creator, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Creator", Email: "creator@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("create creator: %v", err)
+	}
 	viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
 	if err != nil {
 		t.Fatalf("create viewer: %v", err)
 	}
-	creator, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Creator", Email: "creator@example.com", Roles: []string{"creator"}})
+	channel, err := store.CreateChannel(creator.ID, "Original", "music", nil)
 	if err != nil {
-		t.Fatalf("create creator: %v", err)
+		t.Fatalf("create channel: %v", err)
 	}
 
-	liveChannel, err := store.CreateChannel(creator.ID, "Live Now", "gaming", []string{"speedrun"})
-	if err != nil {
-		t.Fatalf("create live channel: %v", err)
+	directory := func(path string) directoryResponse {
+		t.Helper()
+		serve := handler.Directory
+		if path == "/api/directory/live" {
+			serve = handler.DirectoryLive
+		}
+		rec := httptest.NewRecorder()
+		serve(rec, httptest.NewRequest(http.MethodGet, path, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET %s: expected 200, got %d", path, rec.Code)
+		}
+		var resp directoryResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode %s: %v", path, err)
+		}
+		return resp
 	}
-	startingChannel, err := store.CreateChannel(creator.ID, "Starting Soon", "music", []string{"dj"})
-	if err != nil {
-		t.Fatalf("create starting channel: %v", err)
+	publicChannel := func() channelPublicResponse {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, httptest.NewRequest(http.MethodGet, "/api/channels/"+channel.ID, nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("GET channel: expected 200, got %d", rec.Code)
+		}
+		var resp channelPublicResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode channel: %v", err)
+		}
+		return resp
 	}
-	offlineChannel, err := store.CreateChannel(creator.ID, "Offline Show", "tech", []string{"coding"})
-	if err != nil {
-		t.Fatalf("create offline channel: %v", err)
+	ownerRequest := func(method, path string, body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := withUser(httptest.NewRequest(method, path, strings.NewReader(body)), creator)
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		if rec.Code >= http.StatusBadRequest {
+			t.Fatalf("%s %s: unexpected status %d: %s", method, path, rec.Code, rec.Body.String())
+		}
+		return rec
 	}
 
-	liveState := "live"
-	if _, err := store.UpdateChannel(liveChannel.ID, storage.ChannelUpdate{LiveState: &liveState}); err != nil {
-		t.Fatalf("set live state: %v", err)
+	if got := directory("/api/directory").Channels[0].Channel.Title; got != "Original" {
+		t.Fatalf("expected Original, got %q", got)
 	}
-	startingState := "starting"
-	if _, err := store.UpdateChannel(startingChannel.ID, storage.ChannelUpdate{LiveState: &startingState}); err != nil {
-		t.Fatalf("set starting state: %v", err)
+	if got := publicChannel().Title; got != "Original" {
+		t.Fatalf("expected Original, got %q", got)
 	}
 
-	if err := store.FollowChannel(viewer.ID, offlineChannel.ID); err != nil {
-		t.Fatalf("follow offline channel: %v", err)
-	}
-	if err := store.FollowChannel(viewer.ID, liveChannel.ID); err != nil {
-		t.Fatalf("follow live channel: %v", err)
+	// Writes that bypass the API are only picked up once the TTL lapses.
+	if _, err := store.UpdateChannel(channel.ID, storage.ChannelUpdate{Title: stringPtr("Sideloaded")}); err != nil {
+		t.Fatalf("update channel: %v", err)
 	}
-	if err := store.FollowChannel(viewer.ID, startingChannel.ID); err != nil {
-		t.Fatalf("follow starting channel: %v", err)
+	if got := directory("/api/directory").Channels[0].Channel.Title; got != "Original" {
+		t.Fatalf("expected cached Original, got %q", got)
 	}
 
-	req := httptest.NewRequest(http.MethodGet, "/api/directory/following", nil)
-	req = withUser(req, viewer)
-	rec := httptest.NewRecorder()
-
-	handler.DirectoryFollowing(rec, req)
-
-	if rec.Code != http.StatusOK {
-		t.Fatalf("expected status 200, got %d", rec.Code)
+	ownerRequest(http.MethodPatch, "/api/channels/"+channel.ID, `{"title":"Renamed"}`)
+	if got := directory("/api/directory").Channels[0].Channel.Title; got != "Renamed" {
+		t.Fatalf("expected UpdateChannel to invalidate directory, got %q", got)
+	}
+	if got := publicChannel().Title; got != "Renamed" {
+		t.Fatalf("expected UpdateChannel to invalidate channel, got %q", got)
 	}
 
-	var resp directoryResponse
-	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
-		t.Fatalf("decode response: %v", err)
+	if got := directory("/api/directory").Channels[0].FollowerCount; got != 0 {
+		t.Fatalf("expected no followers, got %d", got)
+	}
+	followReq := withUser(httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/follow", nil), viewer)
+	followRec := httptest.NewRecorder()
+	handler.ChannelByID(followRec, followReq)
+	if followRec.Code != http.StatusOK {
+		t.Fatalf("follow: expected 200, got %d", followRec.Code)
+	}
+	if got := directory("/api/directory").Channels[0].FollowerCount; got != 1 {
+		t.Fatalf("expected follow to invalidate follower count, got %d", got)
+	}
+	unfollowReq := withUser(httptest.NewRequest(http.MethodDelete, "/api/channels/"+channel.ID+"/follow", nil), viewer)
+	unfollowRec := httptest.NewRecorder()
+	handler.ChannelByID(unfollowRec, unfollowReq)
+	if unfollowRec.Code != http.StatusOK {
+		t.Fatalf("unfollow: expected 200, got %d", unfollowRec.Code)
+	}
+	if got := directory("/api/directory").Channels[0].FollowerCount; got != 0 {
+		t.Fatalf("expected unfollow to invalidate follower count, got %d", got)
 	}
 
-	wantIDs := []string{startingChannel.ID, liveChannel.ID}
-	if len(resp.Channels) != len(wantIDs) {
-		t.Fatalf("expected %d channels, got %d", len(wantIDs), len(resp.Channels))
+	if got := len(directory("/api/directory/live").Channels); got != 0 {
+		t.Fatalf("expected no live channels, got %d", got)
 	}
-	for i, id := range wantIDs {
-		if resp.Channels[i].Channel.ID != id {
-			t.Fatalf("expected channel %s at index %d, got %s", id, i, resp.Channels[i].Channel.ID)
-		}
+	ownerRequest(http.MethodPost, "/api/channels/"+channel.ID+"/stream/start", `{"renditions":["720p"]}`)
+	if got := len(directory("/api/directory/live").Channels); got != 1 {
+		t.Fatalf("expected StartStream to invalidate live directory, got %d channels", got)
+	}
+	if got := publicChannel().LiveState; got != "live" {
+		t.Fatalf("expected StartStream to invalidate channel, got %q", got)
+	}
+	ownerRequest(http.MethodPost, "/api/channels/"+channel.ID+"/stream/stop", `{"peakConcurrent":3}`)
+	if got := len(directory("/api/directory/live").Channels); got != 0 {
+		t.Fatalf("expected StopStream to invalidate live directory, got %d channels", got)
+	}
+	if got := publicChannel().LiveState; got != "offline" {
+		t.Fatalf("expected StopStream to invalidate channel, got %q", got)
 	}
 }
 
-func TestOAuthProvidersEndpoint(t *testing.T) {
-	handler, _ := newTestHandler(t)
-	stub := &oauthStub{providers: []oauth.ProviderInfo{{Name: "test", DisplayName: "Test"}}}
-	handler.OAuth = stub
+func TestChannelReadCacheServesOnlyPublicProjection(t *testing.T) {
+	handler, store := newTestHandler(t)
+	handler.ReadCache = cache.New(cache.Config{TTL: time.Hour, Metrics: metrics.New()})
 
-	req := httptest.NewRequest(http.MethodGet, "/api/auth/oauth/providers", nil)
-	rec := httptest.NewRecorder()
-	handler.OAuthProviders(rec, req)
+	creator, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Creator", Email: "creator@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("create creator: %v", err)
+	}
+	channel, err := store.CreateChannel(creator.ID, "Mine", "music", nil)
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+
+	get := func(user *models.User) map[string]interface{} {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/channels/"+channel.ID, nil)
+		if user != nil {
+			req = withUser(req, *user)
+		}
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d", rec.Code)
+		}
+		var payload map[string]interface{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+			t.Fatalf("decode: %v", err)
+		}
+		return payload
+	}
+
+	if _, ok := get(&creator)["streamKeyHint"]; !ok {
+		t.Fatal("expected owner to receive the stream key hint")
+	}
+	if _, ok := get(nil)["streamKeyHint"]; ok {
+		t.Fatal("expected anonymous viewer not to receive the stream key hint")
+	}
+	if _, ok := get(&creator)["streamKeyHint"]; !ok {
+		t.Fatal("expected owner to bypass the cached public projection")
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ChannelByID(rec, httptest.NewRequest(http.MethodGet, "/api/channels/missing", nil))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for unknown channel, got %d", rec.Code)
+	}
+}
+
+func TestDirectoryFollowingListsLiveFollowedChannels(t *testing.T) {
+	handler, store := newTestHandler(t)
+
+	viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("create viewer: %v", err)
+	}
+	creator, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Creator", Email: "creator@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("create creator: %v", err)
+	}
+
+	liveChannel, err := store.CreateChannel(creator.ID, "Live Now", "gaming", []string{"speedrun"})
+	if err != nil {
+		t.Fatalf("create live channel: %v", err)
+	}
+	startingChannel, err := store.CreateChannel(creator.ID, "Starting Soon", "music", []string{"dj"})
+	if err != nil {
+		t.Fatalf("create starting channel: %v", err)
+	}
+	offlineChannel, err := store.CreateChannel(creator.ID, "Offline Show", "tech", []string{"coding"})
+	if err != nil {
+		t.Fatalf("create offline channel: %v", err)
+	}
+
+	liveState := "live"
+	if _, err := store.UpdateChannel(liveChannel.ID, storage.ChannelUpdate{LiveState: &liveState}); err != nil {
+		t.Fatalf("set live state: %v", err)
+	}
+	startingState := "starting"
+	if _, err := store.UpdateChannel(startingChannel.ID, storage.ChannelUpdate{LiveState: &startingState}); err != nil {
+		t.Fatalf("set starting state: %v", err)
+	}
+
+	if err := store.FollowChannel(viewer.ID, offlineChannel.ID); err != nil {
+		t.Fatalf("follow offline channel: %v", err)
+	}
+	if err := store.FollowChannel(viewer.ID, liveChannel.ID); err != nil {
+		t.Fatalf("follow live channel: %v", err)
+	}
+	if err := store.FollowChannel(viewer.ID, startingChannel.ID); err != nil {
+		t.Fatalf("follow starting channel: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/directory/following", nil)
+	req = withUser(req, viewer)
+	rec := httptest.NewRecorder()
+
+	handler.DirectoryFollowing(rec, req)
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+
+	var resp directoryResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+
+	wantIDs := []string{startingChannel.ID, liveChannel.ID}
+	if len(resp.Channels) != len(wantIDs) {
+		t.Fatalf("expected %d channels, got %d", len(wantIDs), len(resp.Channels))
+	}
+	for i, id := range wantIDs {
+		if resp.Channels[i].Channel.ID != id {
+			t.Fatalf("expected channel %s at index %d, got %s", id, i, resp.Channels[i].Channel.ID)
+		}
+	}
+}
+
+func TestOAuthProvidersEndpoint(t *testing.T) {
+	handler, _ := newTestHandler(t)
+	stub := &oauthStub{providers: []oauth.ProviderInfo{{Name: "test", DisplayName: "Test"}}}
+	handler.OAuth = stub
+
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/oauth/providers", nil)
+	rec := httptest.NewRecorder()
+	handler.OAuthProviders(rec, req)
 	if rec.Code != http.StatusOK {
 		t.Fatalf("expected status 200, got %d", rec.Code)
 	}
@@ -1728,6 +2261,150 @@ func TestHealthReturnsOKStatusCodeWhenIngestIsDegraded(t *testing.T) {
 	}
 }
 
+// hungIngestRepository serves a recorded ingest snapshot while any live
+// probe hangs until the test ends.
+type hungIngestRepository struct {
+	storage.Repository
+	health    []ingest.HealthStatus
+	checkedAt time.Time
+	hang      chan struct{}
+}
+
+func (r hungIngestRepository) IngestHealth(context.Context) []ingest.HealthStatus {
+	<-r.hang
+	return r.health
+}
+
+func (r hungIngestRepository) LastIngestHealth() ([]ingest.HealthStatus, time.Time) {
+	return r.health, r.checkedAt
+}
+
+// countingRefresher records forced ingest health polls.
+type countingRefresher struct {
+	calls int
+}
+
+func (r *countingRefresher) Refresh(context.Context) error {
+	r.calls++
+	return nil
+}
+
+func TestHealthServesIngestSnapshotWithoutProbing(t *testing.T) {
+	handler, store := newTestHandler(t)
+	checkedAt := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	hang := make(chan struct{})
+	defer close(hang)
+	handler.Store = hungIngestRepository{
+		Repository: store,
+		health:     []ingest.HealthStatus{{Component: "srs", Status: "ok"}, {Component: "transcoder", Status: "error", Detail: "offline"}},
+		checkedAt:  checkedAt,
+		hang:       hang,
+	}
+	handler.Now = func() time.Time { return checkedAt.Add(42 * time.Second) }
+
+	done := make(chan *httptest.ResponseRecorder, 1)
+	go func() {
+		rec := httptest.NewRecorder()
+		handler.Health(rec, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+		done <- rec
+	}()
+	var rec *httptest.ResponseRecorder
+	select {
+	case rec = <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected /healthz to answer from the snapshot instead of probing")
+	}
+
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected status 200, got %d", rec.Code)
+	}
+	var payload map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode health payload: %v", err)
+	}
+	if payload["status"] != "degraded" {
+		t.Fatalf("expected the failing transcoder to degrade the status, got %v", payload["status"])
+	}
+	if services, _ := payload["services"].([]interface{}); len(services) != 2 {
+		t.Fatalf("expected the recorded services, got %v", payload["services"])
+	}
+	if payload["servicesCheckedAt"] != checkedAt.Format(time.RFC3339Nano) {
+		t.Fatalf("expected servicesCheckedAt %s, got %v", checkedAt.Format(time.RFC3339Nano), payload["servicesCheckedAt"])
+	}
+	if age, _ := payload["servicesAgeSeconds"].(float64); age != 42 {
+		t.Fatalf("expected servicesAgeSeconds 42, got %v", payload["servicesAgeSeconds"])
+	}
+}
+
+func TestHealthRefreshRequiresPlatformAdmin(t *testing.T) {
+	handler, store := newTestHandler(t)
+	refresher := &countingRefresher{}
+	handler.IngestHealthPoller = refresher
+	viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.Health(rec, httptest.NewRequest(http.MethodGet, "/healthz?refresh=true", nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected anonymous refresh to be rejected with 401, got %d", rec.Code)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.Health(rec, withUser(httptest.NewRequest(http.MethodGet, "/healthz?refresh=true", nil), viewer))
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected viewer refresh to be rejected with 403, got %d", rec.Code)
+	}
+	if refresher.calls != 0 {
+		t.Fatalf("expected no forced polls, got %d", refresher.calls)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.Health(rec, withUser(httptest.NewRequest(http.MethodGet, "/healthz", nil), viewer))
+	if rec.Code != http.StatusOK || refresher.calls != 0 {
+		t.Fatalf("expected a plain health check to skip the poller, got %d with %d polls", rec.Code, refresher.calls)
+	}
+}
+
+func TestHealthRefreshIsRateLimited(t *testing.T) {
+	handler, store := newTestHandler(t)
+	refresher := &countingRefresher{}
+	handler.IngestHealthPoller = refresher
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	handler.Now = func() time.Time { return now }
+	admin, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Admin", Email: "admin@example.com", Roles: []string{"admin"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	refresh := func() *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.Health(rec, withUser(httptest.NewRequest(http.MethodGet, "/healthz?refresh=true", nil), admin))
+		return rec
+	}
+
+	if rec := refresh(); rec.Code != http.StatusOK || refresher.calls != 1 {
+		t.Fatalf("expected the first refresh to poll, got %d with %d polls", rec.Code, refresher.calls)
+	}
+
+	now = now.Add(4 * time.Second)
+	rec := refresh()
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected a refresh within the cooldown to get 429, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Retry-After"); got != "6" {
+		t.Fatalf("expected Retry-After 6, got %q", got)
+	}
+	if refresher.calls != 1 {
+		t.Fatalf("expected the limited refresh to skip polling, got %d polls", refresher.calls)
+	}
+
+	now = now.Add(6 * time.Second)
+	if rec := refresh(); rec.Code != http.StatusOK || refresher.calls != 2 {
+		t.Fatalf("expected a refresh after the cooldown to poll, got %d with %d polls", rec.Code, refresher.calls)
+	}
+}
+
 func TestReadyIgnoresIngestHealth(t *testing.T) {
 	handler, store := newTestHandler(t)
 	failingServices := []ingest.HealthStatus{{Component: "transcoder", Status: "error", Detail: "offline"}}
@@ -1953,93 +2630,658 @@ func TestChannelStreamLifecycle(t *testing.T) {
 	}
 }
 
-func TestChannelStreamEndpointsUnavailableWithoutIngest(t *testing.T) {
-	handler, store := newTestHandler(t)
-	handler.Store = ingestUnavailableRepo{Repository: store}
+// bootResultController boots every stream with the same result.
+type bootResultController struct {
+	ingest.NoopController
+	result ingest.BootResult
+}
 
-	creator, err := store.CreateUser(storage.CreateUserParams{
-		DisplayName: "Streamer",
-		Email:       "streamer@example.com",
-		Roles:       []string{"creator"},
-	})
+func (c bootResultController) BootStream(ctx context.Context, params ingest.BootParams) (ingest.BootResult, error) {
+	return c.result, nil
+}
+
+// clipController cuts a fixed clip from any live job.
+type clipController struct {
+	bootResultController
+}
+
+func (c *clipController) CaptureClip(ctx context.Context, jobID string, durationSeconds int) (ingest.LiveClip, error) {
+	return ingest.LiveClip{ID: "clip-" + jobID, PlaybackURL: "https://cdn.example/clips/" + jobID + ".mp4", DurationSeconds: durationSeconds}, nil
+}
+
+func TestLiveClipsAreAttributedAndRateLimited(t *testing.T) {
+	store, err := storage.NewStorage(filepath.Join(t.TempDir(), "store.json"), storage.WithIngestController(&clipController{bootResultController{result: ingest.BootResult{
+		PrimaryIngest: "rtmp://ingest.example/live",
+		OriginURL:     "https://origin.example/live",
+		PlaybackURL:   "https://cdn.example/master.m3u8",
+		JobIDs:        []string{"job-1"},
+	}}}), storage.WithIngestRetries(1, 0))
 	if err != nil {
-		t.Fatalf("CreateUser: %v", err)
+		t.Fatalf("NewStorage: %v", err)
 	}
-
-	channel, err := store.CreateChannel(creator.ID, "No Ingest", "gaming", nil)
+	handler := NewHandler(store, auth.NewSessionManager(24*time.Hour))
+	now := time.Date(2026, 3, 1, 20, 0, 0, 0, time.UTC)
+	handler.Now = func() time.Time { return now }
+	handler.LiveClipLimit = 2
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser owner: %v", err)
+	}
+	viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser viewer: %v", err)
+	}
+	other, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Other", Email: "other@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser other: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Highlights", "gaming", nil)
 	if err != nil {
 		t.Fatalf("CreateChannel: %v", err)
 	}
 
-	startPayload := map[string]any{"renditions": []string{"720p"}}
-	body, _ := json.Marshal(startPayload)
-	req := httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/stream/start", bytes.NewReader(body))
-	req = withUser(req, creator)
-	rec := httptest.NewRecorder()
-	handler.ChannelByID(rec, req)
-	if rec.Code != http.StatusServiceUnavailable {
-		t.Fatalf("expected start status 503, got %d", rec.Code)
+	clip := func(user *models.User, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/clip", strings.NewReader(body))
+		if user != nil {
+			req = withUser(req, *user)
+		}
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		return rec
 	}
 
-	stored, ok := store.GetChannel(channel.ID)
-	if !ok {
-		t.Fatalf("expected to reload channel %s", channel.ID)
-	}
-	if stored.LiveState != "offline" {
-		t.Fatalf("expected offline channel after failed start, got %s", stored.LiveState)
+	if rec := clip(nil, ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for anonymous clips, got %d", rec.Code)
 	}
-	if stored.CurrentSessionID != nil {
-		t.Fatalf("expected current session to remain nil, got %v", stored.CurrentSessionID)
+	rec := clip(&viewer, "")
+	if rec.Code != http.StatusConflict || decodeAPIError(t, rec.Body.Bytes()).Error.Code != "channel_offline" {
+		t.Fatalf("expected 409 channel_offline, got %d: %s", rec.Code, rec.Body.String())
 	}
 
 	session, err := store.StartStream(channel.ID, []string{"720p"})
 	if err != nil {
 		t.Fatalf("StartStream: %v", err)
 	}
-
-	stopPayload := map[string]any{"peakConcurrent": 15}
-	body, _ = json.Marshal(stopPayload)
-	req = httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/stream/stop", bytes.NewReader(body))
-	req = withUser(req, creator)
-	rec = httptest.NewRecorder()
-	handler.ChannelByID(rec, req)
-	if rec.Code != http.StatusServiceUnavailable {
-		t.Fatalf("expected stop status 503, got %d", rec.Code)
+	if rec := clip(&viewer, `{"durationSeconds":61}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a clip over the maximum, got %d", rec.Code)
 	}
 
-	stored, ok = store.GetChannel(channel.ID)
-	if !ok {
-		t.Fatalf("expected to reload channel %s after stop", channel.ID)
+	rec = clip(&viewer, "")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
 	}
-	if stored.CurrentSessionID == nil || *stored.CurrentSessionID != session.ID {
-		t.Fatalf("expected current session to remain %s, got %v", session.ID, stored.CurrentSessionID)
+	var created clipExportResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode clip: %v", err)
 	}
-	if stored.LiveState != "live" {
-		t.Fatalf("expected channel to remain live, got %s", stored.LiveState)
+	if created.CreatedBy != viewer.ID || created.SessionID != session.ID || created.RecordingID != "" {
+		t.Fatalf("expected clip by %s on session %s without a recording, got %+v", viewer.ID, session.ID, created)
+	}
+	if created.Title != channel.Title || created.EndSeconds-created.StartSeconds != storage.DefaultLiveClipSeconds {
+		t.Fatalf("expected a default-length clip titled after the channel, got %+v", created)
 	}
-}
 
-func TestSRSHookRejectsMissingToken(t *testing.T) {
-	handler, store := newTestHandler(t)
-	handler.SRSHookToken = "secret"
-	creator, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Streamer", Email: "hook@example.com", Roles: []string{"creator"}})
-	if err != nil {
-		t.Fatalf("create user: %v", err)
+	if rec := clip(&viewer, `{"title":"Second","durationSeconds":45}`); rec.Code != http.StatusCreated {
+		t.Fatalf("expected second clip to fit the limit, got %d: %s", rec.Code, rec.Body.String())
 	}
-	channel, err := store.CreateChannel(creator.ID, "Hooked", "gaming", nil)
-	if err != nil {
-		t.Fatalf("create channel: %v", err)
+	rec = clip(&viewer, "")
+	if rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected 429 once the limit is spent, got %d", rec.Code)
 	}
-	req := httptest.NewRequest(http.MethodPost, "/api/ingest/srs-hook", strings.NewReader(fmt.Sprintf(`{"action":"on_publish","stream":"%s"}`, channel.StreamKey)))
-	rec := httptest.NewRecorder()
-	handler.SRSHook(rec, req)
-	if rec.Code != http.StatusUnauthorized {
-		t.Fatalf("expected unauthorized, got %d", rec.Code)
+	if got := rec.Header().Get("Retry-After"); got != "60" {
+		t.Fatalf("expected Retry-After of 60, got %q", got)
+	}
+	if rec := clip(&other, ""); rec.Code != http.StatusCreated {
+		t.Fatalf("expected another viewer to have their own limit, got %d", rec.Code)
+	}
+	now = now.Add(time.Minute)
+	if rec := clip(&viewer, ""); rec.Code != http.StatusCreated {
+		t.Fatalf("expected the limit to reset after the window, got %d", rec.Code)
 	}
 }
 
-func TestSRSHookPublishAndUnpublish(t *testing.T) {
-	store, err := storage.NewStorage(t.TempDir()+"/store.json", storage.WithIngestController(ingest.NoopController{}), storage.WithIngestRetries(1, 0))
+func TestChannelSessionDetailFiltersIngestDetails(t *testing.T) {
+	store, err := storage.NewStorage(filepath.Join(t.TempDir(), "store.json"), storage.WithIngestController(bootResultController{result: ingest.BootResult{
+		PrimaryIngest: "rtmp://ingest.example/live",
+		OriginURL:     "https://origin.example/live",
+		PlaybackURL:   "https://cdn.example/master.m3u8",
+		JobIDs:        []string{"job-1"},
+		Renditions:    []ingest.Rendition{{Name: "720p", ManifestURL: "https://cdn.example/720p.m3u8", Bitrate: 3000}},
+	}}), storage.WithIngestRetries(1, 0))
+	if err != nil {
+		t.Fatalf("NewStorage: %v", err)
+	}
+	handler := NewHandler(store, auth.NewSessionManager(24*time.Hour))
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser owner: %v", err)
+	}
+	moderator, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Mod", Email: "mod@example.com", Roles: []string{"moderator"}})
+	if err != nil {
+		t.Fatalf("CreateUser moderator: %v", err)
+	}
+	viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser viewer: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Sessions", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/stream/start", strings.NewReader(`{"renditions":["720p","480p"]}`))
+	req = withUser(req, owner)
+	rec := httptest.NewRecorder()
+	handler.ChannelByID(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected start status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var started sessionResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &started); err != nil {
+		t.Fatalf("decode start response: %v", err)
+	}
+	if !reflect.DeepEqual(started.IngestEndpoints, []string{"rtmp://ingest.example/live"}) || started.PlaybackURL != "https://cdn.example/master.m3u8" {
+		t.Fatalf("expected start response to include ingest and playback URLs, got %+v", started)
+	}
+	wantSettings := &sessionSettingsResponse{
+		RequestedRenditions: []string{"720p", "480p"},
+		Ladder:              []ladderRenditionResponse{{Name: "720p", Bitrate: 3000}},
+		LatencyMode:         models.LatencyModeLow,
+	}
+	if !reflect.DeepEqual(started.Settings, wantSettings) {
+		t.Fatalf("expected settings %+v, got %+v", wantSettings, started.Settings)
+	}
+
+	fetch := func(user *models.User) map[string]json.RawMessage {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/channels/"+channel.ID+"/sessions/"+started.ID, nil)
+		if user != nil {
+			req = withUser(req, *user)
+		}
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected session detail status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var fields map[string]json.RawMessage
+		if err := json.Unmarshal(rec.Body.Bytes(), &fields); err != nil {
+			t.Fatalf("decode session detail: %v", err)
+		}
+		return fields
+	}
+	privileged := []string{"ingestEndpoints", "originUrl", "ingestJobIds"}
+	for name, user := range map[string]*models.User{"owner": &owner, "moderator": &moderator} {
+		fields := fetch(user)
+		for _, key := range append(privileged, "playbackUrl", "settings") {
+			if _, ok := fields[key]; !ok {
+				t.Fatalf("expected %s to see %s, got %v", name, key, fields)
+			}
+		}
+	}
+	for name, user := range map[string]*models.User{"viewer": &viewer, "anonymous": nil} {
+		fields := fetch(user)
+		for _, key := range privileged {
+			if _, ok := fields[key]; ok {
+				t.Fatalf("expected %s not to see %s", name, key)
+			}
+		}
+		if _, ok := fields["playbackUrl"]; !ok {
+			t.Fatalf("expected %s to see the public playback URL", name)
+		}
+		if _, ok := fields["settings"]; !ok {
+			t.Fatalf("expected %s to see the settings snapshot", name)
+		}
+	}
+
+	restriction := models.PlaybackRestrictionFollowers
+	if _, err := store.UpdateChannel(channel.ID, storage.ChannelUpdate{PlaybackRestriction: &restriction}); err != nil {
+		t.Fatalf("UpdateChannel: %v", err)
+	}
+	if _, ok := fetch(&viewer)["playbackUrl"]; ok {
+		t.Fatal("expected restricted channel to withhold the playback URL from viewers")
+	}
+	if _, ok := fetch(&owner)["playbackUrl"]; !ok {
+		t.Fatal("expected owner to keep the playback URL on a restricted channel")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/channels/"+channel.ID+"/sessions/missing", nil)
+	rec = httptest.NewRecorder()
+	handler.ChannelByID(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected unknown session status 404, got %d", rec.Code)
+	}
+}
+
+// limitRecordingController records the caps each boot was asked to apply and
+// optionally fails the boot with err.
+type limitRecordingController struct {
+	ingest.NoopController
+	limits *models.TranscodeLimits
+	err    error
+	calls  int
+}
+
+func (c *limitRecordingController) BootStream(ctx context.Context, params ingest.BootParams) (ingest.BootResult, error) {
+	c.calls++
+	c.limits = params.TranscodeLimits
+	if c.err != nil {
+		return ingest.BootResult{}, c.err
+	}
+	return ingest.BootResult{PlaybackURL: "https://cdn.example/master.m3u8"}, nil
+}
+
+func TestStreamStartEnforcesTranscodeLimits(t *testing.T) {
+	controller := &limitRecordingController{}
+	store, err := storage.NewStorage(filepath.Join(t.TempDir(), "store.json"), storage.WithIngestController(controller), storage.WithIngestRetries(1, 0))
+	if err != nil {
+		t.Fatalf("NewStorage: %v", err)
+	}
+	handler := NewHandler(store, auth.NewSessionManager(24*time.Hour))
+	handler.TranscodeLimits = models.TranscodeLimits{MaxHeight: 720, MaxBitrate: 8000}
+	handler.LadderProfiles = []ingest.Rendition{{Name: "1080p", Bitrate: 6000}, {Name: "720p", Bitrate: 4000}, {Name: "480p", Bitrate: 2500}}
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Limits", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+
+	start := func(renditions ...string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(map[string]interface{}{"renditions": renditions})
+		req := withUser(httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/stream/start", bytes.NewReader(body)), owner)
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		return rec
+	}
+
+	rec := start("1080p", "720p")
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 for 1080p over a 720 cap, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if resp := decodeAPIError(t, rec.Body.Bytes()); resp.Error.Code != ingest.LimitReasonResolution || !strings.Contains(resp.Error.Message, "1080") || !strings.Contains(resp.Error.Message, "720") {
+		t.Fatalf("unexpected resolution error: %+v", resp.Error)
+	}
+	rec = start("720p", "480p", "480p")
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422 for 9000 kbps over an 8000 cap, got %d", rec.Code)
+	}
+	if resp := decodeAPIError(t, rec.Body.Bytes()); resp.Error.Code != ingest.LimitReasonBitrate || !strings.Contains(resp.Error.Message, "9000") {
+		t.Fatalf("unexpected bitrate error: %+v", resp.Error)
+	}
+	if _, live := store.CurrentStreamSession(channel.ID); live {
+		t.Fatal("expected rejected ladders not to start a session")
+	}
+
+	override := models.TranscodeLimits{MaxHeight: 1080}
+	if _, err := store.UpdateChannel(channel.ID, storage.ChannelUpdate{TranscodeLimits: &override}); err != nil {
+		t.Fatalf("UpdateChannel: %v", err)
+	}
+	rec = start("1080p")
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected the channel override to allow 1080p, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if controller.limits == nil || controller.limits.MaxHeight != 1080 {
+		t.Fatalf("expected the override to reach ingest, got %+v", controller.limits)
+	}
+}
+
+func TestStreamStartRelaysTranscoderLimitRejection(t *testing.T) {
+	controller := &limitRecordingController{err: &ingest.TranscodeLimitError{
+		Reason:  ingest.LimitReasonBitrate,
+		Message: "ladder totals 12000 kbps, over the 5000 kbps limit",
+		Limit:   5000,
+		Value:   12000,
+	}}
+	store, err := storage.NewStorage(filepath.Join(t.TempDir(), "store.json"), storage.WithIngestController(controller), storage.WithIngestRetries(3, 0))
+	if err != nil {
+		t.Fatalf("NewStorage: %v", err)
+	}
+	handler := NewHandler(store, auth.NewSessionManager(24*time.Hour))
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Limits", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+
+	req := withUser(httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/stream/start", strings.NewReader(`{}`)), owner)
+	rec := httptest.NewRecorder()
+	handler.ChannelByID(rec, req)
+	if rec.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("expected 422, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if resp := decodeAPIError(t, rec.Body.Bytes()); resp.Error.Code != ingest.LimitReasonBitrate || !strings.Contains(resp.Error.Message, "12000") {
+		t.Fatalf("unexpected error: %+v", resp.Error)
+	}
+	if controller.calls != 1 {
+		t.Fatalf("expected a limit rejection not to be retried, got %d boots", controller.calls)
+	}
+	if updated, _ := store.GetChannel(channel.ID); updated.LiveState != "offline" {
+		t.Fatalf("expected channel to return offline, got %s", updated.LiveState)
+	}
+}
+
+func TestChannelTranscodeLimitsRequireAdmin(t *testing.T) {
+	handler, store := newTestHandler(t)
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser owner: %v", err)
+	}
+	admin, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Admin", Email: "admin@example.com", Roles: []string{"admin"}})
+	if err != nil {
+		t.Fatalf("CreateUser admin: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Limits", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+
+	patch := func(user models.User, body string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(http.MethodPatch, "/api/channels/"+channel.ID, strings.NewReader(body)), user)
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		return rec
+	}
+
+	if rec := patch(owner, `{"transcodeLimits":{"maxHeight":1080}}`); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected owner to be forbidden, got %d", rec.Code)
+	}
+	rec := patch(admin, `{"transcodeLimits":{"maxHeight":1080,"maxRenditions":2}}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected admin update to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp channelResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode channel: %v", err)
+	}
+	if resp.TranscodeLimits == nil || resp.TranscodeLimits.MaxHeight != 1080 || resp.TranscodeLimits.MaxRenditions != 2 {
+		t.Fatalf("unexpected transcode limits %+v", resp.TranscodeLimits)
+	}
+	if rec := patch(admin, `{"transcodeLimits":{"maxBitrate":-1}}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected negative limits to be rejected, got %d", rec.Code)
+	}
+	if rec := patch(admin, `{"transcodeLimits":{}}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected clearing the override to succeed, got %d", rec.Code)
+	}
+	if updated, _ := store.GetChannel(channel.ID); updated.TranscodeLimits != nil {
+		t.Fatalf("expected override cleared, got %+v", updated.TranscodeLimits)
+	}
+}
+
+func TestChannelPreviewServesCachedFrame(t *testing.T) {
+	handler, store := newTestHandler(t)
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Live", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	repo := &previewRepository{Repository: store}
+	handler.Store = repo
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	handler.Now = func() time.Time { return now }
+
+	fetch := func() *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/channels/"+channel.ID+"/preview", nil)
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		return rec
+	}
+
+	rec := fetch()
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for offline channel, got %d", rec.Code)
+	}
+	if repo.captures != 0 {
+		t.Fatalf("expected no capture for offline channel, got %d", repo.captures)
+	}
+
+	if _, err := store.StartStream(channel.ID, []string{"720p"}); err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	rec = fetch()
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 before a frame exists, got %d", rec.Code)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "no-store" {
+		t.Fatalf("expected missing preview to be uncacheable, got %q", got)
+	}
+
+	repo.frame = ingest.Frame{Data: []byte{0xff, 0xd8, 0xff, 0xd9}, ContentType: "image/jpeg"}
+	rec = fetch()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != "image/jpeg" {
+		t.Fatalf("expected image/jpeg, got %q", got)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "public, max-age=30" {
+		t.Fatalf("expected 30s public caching, got %q", got)
+	}
+	if !bytes.Equal(rec.Body.Bytes(), repo.frame.Data) {
+		t.Fatalf("expected frame bytes in response")
+	}
+
+	now = now.Add(10 * time.Second)
+	rec = fetch()
+	if got := rec.Header().Get("Cache-Control"); got != "public, max-age=20" {
+		t.Fatalf("expected remaining max-age 20, got %q", got)
+	}
+	if repo.captures != 2 {
+		t.Fatalf("expected cached frame to be reused, got %d captures", repo.captures)
+	}
+
+	now = now.Add(30 * time.Second)
+	fetch()
+	if repo.captures != 3 {
+		t.Fatalf("expected expired frame to be recaptured, got %d captures", repo.captures)
+	}
+}
+
+func TestLivePreviewThumbnailURL(t *testing.T) {
+	started := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	ended := started.Add(time.Hour)
+	session := models.StreamSession{StartedAt: started, PreviewURL: "https://cdn.example/live/job-1/preview.jpg"}
+
+	cases := []struct {
+		name    string
+		session models.StreamSession
+		now     time.Time
+		want    string
+	}{
+		{name: "before the first capture", session: session, now: started.Add(10 * time.Second)},
+		{name: "first capture", session: session, now: started.Add(channelPreviewTTL), want: session.PreviewURL + "?v=1"},
+		{name: "later capture", session: session, now: started.Add(95 * time.Second), want: session.PreviewURL + "?v=3"},
+		{
+			name:    "existing query",
+			session: models.StreamSession{StartedAt: started, PreviewURL: "https://cdn.example/live/job-1/preview.jpg?token=abc"},
+			now:     started.Add(time.Minute),
+			want:    "https://cdn.example/live/job-1/preview.jpg?token=abc&v=2",
+		},
+		{name: "no preview", session: models.StreamSession{StartedAt: started}, now: started.Add(time.Minute)},
+		{name: "ended", session: models.StreamSession{StartedAt: started, EndedAt: &ended, PreviewURL: session.PreviewURL}, now: ended},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := livePreviewThumbnailURL(tc.session, tc.now); got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
+func TestDirectoryIncludesLivePreviewThumbnails(t *testing.T) {
+	previewURL := "https://cdn.example/live/job-1/preview.jpg"
+	store, err := storage.NewStorage(filepath.Join(t.TempDir(), "store.json"), storage.WithIngestController(bootResultController{result: ingest.BootResult{
+		PlaybackURL: "https://cdn.example/master.m3u8",
+		JobIDs:      []string{"job-1"},
+		PreviewURL:  previewURL,
+	}}), storage.WithIngestRetries(1, 0))
+	if err != nil {
+		t.Fatalf("NewStorage: %v", err)
+	}
+	handler := NewHandler(store, auth.NewSessionManager(24*time.Hour))
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	live, err := store.CreateChannel(owner.ID, "Live", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel live: %v", err)
+	}
+	offline, err := store.CreateChannel(owner.ID, "Offline", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel offline: %v", err)
+	}
+	restricted, err := store.CreateChannel(owner.ID, "Followers only", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel restricted: %v", err)
+	}
+	if _, err := store.UpdateChannel(restricted.ID, storage.ChannelUpdate{PlaybackRestriction: stringPtr(models.PlaybackRestrictionFollowers)}); err != nil {
+		t.Fatalf("restrict channel: %v", err)
+	}
+	session, err := store.StartStream(live.ID, []string{"720p"})
+	if err != nil {
+		t.Fatalf("StartStream live: %v", err)
+	}
+	if _, err := store.StartStream(restricted.ID, []string{"720p"}); err != nil {
+		t.Fatalf("StartStream restricted: %v", err)
+	}
+	handler.Now = func() time.Time { return session.StartedAt.Add(65 * time.Second) }
+
+	req := httptest.NewRequest(http.MethodGet, "/api/directory", nil)
+	rec := httptest.NewRecorder()
+	handler.Directory(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "public, max-age=5" {
+		t.Fatalf("expected short public caching, got %q", got)
+	}
+	var resp directoryResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode directory: %v", err)
+	}
+	previews := make(map[string]string)
+	for _, entry := range resp.Channels {
+		previews[entry.Channel.ID] = entry.PreviewThumbnailURL
+	}
+	if got := previews[live.ID]; got != previewURL+"?v=2" {
+		t.Fatalf("expected versioned preview for the live channel, got %q", got)
+	}
+	if got, ok := previews[offline.ID]; !ok || got != "" {
+		t.Fatalf("expected no preview for the offline channel, got %q (listed %v)", got, ok)
+	}
+	if got := previews[restricted.ID]; got != "" {
+		t.Fatalf("expected no preview for a restricted channel, got %q", got)
+	}
+	if strings.Contains(rec.Body.String(), `"previewThumbnailUrl":""`) {
+		t.Fatalf("expected the field to be omitted when empty: %s", rec.Body.String())
+	}
+
+	liveChannel, ok := store.GetChannel(live.ID)
+	if !ok {
+		t.Fatalf("expected live channel %s", live.ID)
+	}
+	handler.Now = func() time.Time { return session.StartedAt.Add(5 * time.Second) }
+	if got := handler.buildDirectoryResponse([]models.Channel{liveChannel}).Channels[0].PreviewThumbnailURL; got != "" {
+		t.Fatalf("expected no preview before the first capture, got %q", got)
+	}
+}
+
+func TestChannelStreamEndpointsUnavailableWithoutIngest(t *testing.T) {
+	handler, store := newTestHandler(t)
+	handler.Store = ingestUnavailableRepo{Repository: store}
+
+	creator, err := store.CreateUser(storage.CreateUserParams{
+		DisplayName: "Streamer",
+		Email:       "streamer@example.com",
+		Roles:       []string{"creator"},
+	})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	channel, err := store.CreateChannel(creator.ID, "No Ingest", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+
+	startPayload := map[string]any{"renditions": []string{"720p"}}
+	body, _ := json.Marshal(startPayload)
+	req := httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/stream/start", bytes.NewReader(body))
+	req = withUser(req, creator)
+	rec := httptest.NewRecorder()
+	handler.ChannelByID(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected start status 503, got %d", rec.Code)
+	}
+
+	stored, ok := store.GetChannel(channel.ID)
+	if !ok {
+		t.Fatalf("expected to reload channel %s", channel.ID)
+	}
+	if stored.LiveState != "offline" {
+		t.Fatalf("expected offline channel after failed start, got %s", stored.LiveState)
+	}
+	if stored.CurrentSessionID != nil {
+		t.Fatalf("expected current session to remain nil, got %v", stored.CurrentSessionID)
+	}
+
+	session, err := store.StartStream(channel.ID, []string{"720p"})
+	if err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+
+	stopPayload := map[string]any{"peakConcurrent": 15}
+	body, _ = json.Marshal(stopPayload)
+	req = httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/stream/stop", bytes.NewReader(body))
+	req = withUser(req, creator)
+	rec = httptest.NewRecorder()
+	handler.ChannelByID(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected stop status 503, got %d", rec.Code)
+	}
+
+	stored, ok = store.GetChannel(channel.ID)
+	if !ok {
+		t.Fatalf("expected to reload channel %s after stop", channel.ID)
+	}
+	if stored.CurrentSessionID == nil || *stored.CurrentSessionID != session.ID {
+		t.Fatalf("expected current session to remain %s, got %v", session.ID, stored.CurrentSessionID)
+	}
+	if stored.LiveState != "live" {
+		t.Fatalf("expected channel to remain live, got %s", stored.LiveState)
+	}
+}
+
+func TestSRSHookRejectsMissingToken(t *testing.T) {
+	handler, store := newTestHandler(t)
+	handler.SRSHookToken = "secret"
+	creator, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Streamer", Email: "hook@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	channel, err := store.CreateChannel(creator.ID, "Hooked", "gaming", nil)
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/ingest/srs-hook", strings.NewReader(fmt.Sprintf(`{"action":"on_publish","stream":"%s"}`, channel.StreamKey)))
+	rec := httptest.NewRecorder()
+	handler.SRSHook(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected unauthorized, got %d", rec.Code)
+	}
+}
+
+func TestSRSHookPublishAndUnpublish(t *testing.T) {
+	store, err := storage.NewStorage(t.TempDir()+"/store.json", storage.WithIngestController(ingest.NoopController{}), storage.WithIngestRetries(1, 0))
 	if err != nil {
 		t.Fatalf("NewStorage: %v", err)
 	}
@@ -2206,9 +3448,19 @@ func TestRotateStreamKeyEndpoint(t *testing.T) {
 	if !ok {
 		t.Fatalf("channel %s missing after rotation", channel.ID)
 	}
-	if updated.StreamKey != resp.StreamKey {
-		t.Fatalf("expected store stream key %s, got %s", resp.StreamKey, updated.StreamKey)
+	if !storage.StreamKeyMatches(updated, resp.StreamKey) {
+		t.Fatal("expected store to hold the hash of the rotated stream key")
 	}
+	if updated.StreamKey != "" {
+		t.Fatal("expected store to drop the plaintext stream key")
+	}
+	if resp.StreamKeyNotice == "" {
+		t.Fatal("expected rotate response to explain the key cannot be shown again")
+	}
+	if resp.StreamKeyHint == "" || resp.StreamKeyHint != updated.StreamKeyHint {
+		t.Fatalf("expected stream key hint %q, got %q", updated.StreamKeyHint, resp.StreamKeyHint)
+	}
+	ownerKey := resp.StreamKey
 
 	// Viewer without access is forbidden.
 	req = httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/stream/rotate", nil)
@@ -2230,16 +3482,19 @@ func TestRotateStreamKeyEndpoint(t *testing.T) {
 	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
 		t.Fatalf("decode admin rotate response: %v", err)
 	}
-	if resp.StreamKey == updated.StreamKey {
-		t.Fatalf("expected admin rotation to change stream key from %s", updated.StreamKey)
+	if resp.StreamKey == ownerKey {
+		t.Fatalf("expected admin rotation to change stream key from %s", ownerKey)
 	}
 
 	latest, ok := store.GetChannel(channel.ID)
 	if !ok {
 		t.Fatalf("channel %s missing after admin rotation", channel.ID)
 	}
-	if latest.StreamKey != resp.StreamKey {
-		t.Fatalf("expected final stream key %s, got %s", resp.StreamKey, latest.StreamKey)
+	if !storage.StreamKeyMatches(latest, resp.StreamKey) {
+		t.Fatal("expected final stream key hash to match admin rotation")
+	}
+	if storage.StreamKeyMatches(latest, ownerKey) {
+		t.Fatal("expected previous stream key to stop matching after rotation")
 	}
 }
 
@@ -2277,135 +3532,426 @@ func TestChannelsListPermissions(t *testing.T) {
 		t.Fatalf("CreateChannel: %v", err)
 	}
 
-	req := httptest.NewRequest(http.MethodGet, "/api/channels", nil)
-	req = withUser(req, creator)
-	rec := httptest.NewRecorder()
-	handler.Channels(rec, req)
-	if rec.Code != http.StatusOK {
-		t.Fatalf("expected creator list status 200, got %d", rec.Code)
-	}
-	var creatorResponse []channelResponse
-	if err := json.Unmarshal(rec.Body.Bytes(), &creatorResponse); err != nil {
-		t.Fatalf("decode creator response: %v", err)
-	}
-	if len(creatorResponse) != 1 {
-		t.Fatalf("expected one channel for creator, got %d", len(creatorResponse))
+	req := httptest.NewRequest(http.MethodGet, "/api/channels", nil)
+	req = withUser(req, creator)
+	rec := httptest.NewRecorder()
+	handler.Channels(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected creator list status 200, got %d", rec.Code)
+	}
+	var creatorResponse []channelResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &creatorResponse); err != nil {
+		t.Fatalf("decode creator response: %v", err)
+	}
+	if len(creatorResponse) != 1 {
+		t.Fatalf("expected one channel for creator, got %d", len(creatorResponse))
+	}
+	if creatorResponse[0].StreamKeyHint == "" {
+		t.Fatal("expected stream key hint for creator-owned channel")
+	}
+	if creatorResponse[0].StreamKey != "" {
+		t.Fatal("expected channel list to omit the plaintext stream key")
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/channels?ownerId="+creator.ID, nil)
+	req = withUser(req, viewer)
+	rec = httptest.NewRecorder()
+	handler.Channels(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected viewer status 403, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/channels?ownerId="+creator.ID, nil)
+	req = withUser(req, admin)
+	rec = httptest.NewRecorder()
+	handler.Channels(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected admin list status 200, got %d", rec.Code)
+	}
+	var adminResponse []channelResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &adminResponse); err != nil {
+		t.Fatalf("decode admin response: %v", err)
+	}
+	if len(adminResponse) != 1 {
+		t.Fatalf("expected one channel for admin, got %d", len(adminResponse))
+	}
+	if adminResponse[0].StreamKeyHint != channel.StreamKeyHint {
+		t.Fatalf("expected admin to receive stream key hint %s, got %s", channel.StreamKeyHint, adminResponse[0].StreamKeyHint)
+	}
+}
+
+func TestChannelRecordingPolicySetting(t *testing.T) {
+	handler, store := newTestHandler(t)
+
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser owner: %v", err)
+	}
+	viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser viewer: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Studio", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+
+	patch := func(user models.User, body string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(http.MethodPatch, "/api/channels/"+channel.ID, strings.NewReader(body)), user)
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		return rec
+	}
+
+	rec := patch(owner, `{"recordingPolicy":"auto-publish"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 updating recording policy, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var payload channelResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode channel response: %v", err)
+	}
+	if payload.RecordingPolicy != models.RecordingPolicyAutoPublish {
+		t.Fatalf("expected auto-publish in response, got %q", payload.RecordingPolicy)
+	}
+
+	if rec := patch(owner, `{"recordingPolicy":"keep-forever"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for unknown recording policy, got %d", rec.Code)
+	}
+	if rec := patch(viewer, `{"recordingPolicy":"discard"}`); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for non-owner, got %d", rec.Code)
+	}
+	if stored, _ := store.GetChannel(channel.ID); stored.RecordingPolicy != models.RecordingPolicyAutoPublish {
+		t.Fatalf("expected rejected updates to keep auto-publish, got %q", stored.RecordingPolicy)
+	}
+}
+
+func TestChannelByIDTrailingSlashMatchesBaseRoute(t *testing.T) {
+	handler, store := newTestHandler(t)
+
+	owner, err := store.CreateUser(storage.CreateUserParams{
+		DisplayName: "Owner",
+		Email:       "owner@example.com",
+	})
+	if err != nil {
+		t.Fatalf("CreateUser owner: %v", err)
+	}
+
+	channel, err := store.CreateChannel(owner.ID, "Studio", "gaming", []string{"retro"})
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+
+	baseReq := httptest.NewRequest(http.MethodGet, "/api/channels/"+channel.ID, nil)
+	baseRec := httptest.NewRecorder()
+	handler.ChannelByID(baseRec, baseReq)
+	if baseRec.Code != http.StatusOK {
+		t.Fatalf("expected base route status 200, got %d", baseRec.Code)
+	}
+
+	slashReq := httptest.NewRequest(http.MethodGet, "/api/channels/"+channel.ID+"/", nil)
+	slashRec := httptest.NewRecorder()
+	handler.ChannelByID(slashRec, slashReq)
+	if slashRec.Code != http.StatusOK {
+		t.Fatalf("expected trailing slash status 200, got %d", slashRec.Code)
+	}
+
+	var basePayload, slashPayload channelPublicResponse
+	if err := json.Unmarshal(baseRec.Body.Bytes(), &basePayload); err != nil {
+		t.Fatalf("decode base response: %v", err)
+	}
+	if err := json.Unmarshal(slashRec.Body.Bytes(), &slashPayload); err != nil {
+		t.Fatalf("decode trailing slash response: %v", err)
+	}
+	if !reflect.DeepEqual(basePayload, slashPayload) {
+		t.Fatalf("expected trailing slash response to match base route\nbase: %#v\nslash: %#v", basePayload, slashPayload)
+	}
+}
+
+func TestChatEndpointsLimit(t *testing.T) {
+	handler, store := newTestHandler(t)
+	user, err := store.CreateUser(storage.CreateUserParams{
+		DisplayName: "Alice",
+		Email:       "alice@example.com",
+	})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(user.ID, "My Channel", "", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+
+	for i := 0; i < 3; i++ {
+		payload := map[string]interface{}{
+			"userId":  user.ID,
+			"content": "message",
+		}
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/chat", bytes.NewReader(body))
+		req = withUser(req, user)
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected chat status 201, got %d", rec.Code)
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/channels/"+channel.ID+"/chat?limit=2", nil)
+	req = withUser(req, user)
+	rec := httptest.NewRecorder()
+	handler.ChannelByID(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected chat list status 200, got %d", rec.Code)
+	}
+	var messages []chatMessageResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &messages); err != nil {
+		t.Fatalf("decode chat response: %v", err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("expected 2 messages, got %d", len(messages))
+	}
+}
+
+func TestChatPostDeduplicatesClientMessageIDs(t *testing.T) {
+	handler, store := newTestHandler(t)
+	user, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(user.ID, "My Channel", "", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	post := func(clientID string) *httptest.ResponseRecorder {
+		t.Helper()
+		body, _ := json.Marshal(map[string]any{"userId": user.ID, "content": "hello", "clientMessageId": clientID})
+		req := withUser(httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/chat", bytes.NewReader(body)), user)
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		return rec
+	}
+
+	if rec := post("retry-1"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a non-UUID client id, got %d: %s", rec.Code, rec.Body.String())
+	}
+	const clientID = "9a8b7c6d-5e4f-4a3b-8c2d-1e0f9a8b7c6d"
+	var first, second chatMessageResponse
+	for _, dest := range []*chatMessageResponse{&first, &second} {
+		rec := post(clientID)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+		if err := json.Unmarshal(rec.Body.Bytes(), dest); err != nil {
+			t.Fatalf("decode chat response: %v", err)
+		}
+	}
+	if first.ClientMessageID != clientID || second.ID != first.ID {
+		t.Fatalf("expected the retry to return the original message, got %+v then %+v", first, second)
+	}
+	if messages, err := store.ListChatMessages(channel.ID, 0); err != nil || len(messages) != 1 {
+		t.Fatalf("expected one stored message, got %d (%v)", len(messages), err)
+	}
+}
+
+func TestChatPostAppliesChannelContentSettings(t *testing.T) {
+	handler, store := newTestHandler(t)
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "My Channel", "", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+
+	patch := func(body string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := withUser(httptest.NewRequest(http.MethodPatch, "/api/channels/"+channel.ID, strings.NewReader(body)), owner)
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		return rec
 	}
-	if creatorResponse[0].StreamKey == "" {
-		t.Fatal("expected stream key for creator-owned channel")
+	if rec := patch(`{"chatMaxCapsPercent": 150}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an invalid caps percentage, got %d: %s", rec.Code, rec.Body.String())
 	}
-
-	req = httptest.NewRequest(http.MethodGet, "/api/channels?ownerId="+creator.ID, nil)
-	req = withUser(req, viewer)
-	rec = httptest.NewRecorder()
-	handler.Channels(rec, req)
-	if rec.Code != http.StatusForbidden {
-		t.Fatalf("expected viewer status 403, got %d", rec.Code)
+	rec := patch(`{"chatMaxCapsPercent": 60, "chatRestrictLinks": true}`)
+	var updated channelResponse
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &updated) != nil || updated.ChatMaxCapsPercent != 60 || !updated.ChatRestrictLinks {
+		t.Fatalf("expected the chat settings to be saved, got %d: %s", rec.Code, rec.Body.String())
 	}
 
-	req = httptest.NewRequest(http.MethodGet, "/api/channels?ownerId="+creator.ID, nil)
-	req = withUser(req, admin)
-	rec = httptest.NewRecorder()
-	handler.Channels(rec, req)
-	if rec.Code != http.StatusOK {
-		t.Fatalf("expected admin list status 200, got %d", rec.Code)
+	post := func(author models.User, content string) *httptest.ResponseRecorder {
+		t.Helper()
+		body, _ := json.Marshal(map[string]any{"userId": author.ID, "content": content})
+		req := withUser(httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/chat", bytes.NewReader(body)), author)
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		return rec
 	}
-	var adminResponse []channelResponse
-	if err := json.Unmarshal(rec.Body.Bytes(), &adminResponse); err != nil {
-		t.Fatalf("decode admin response: %v", err)
+	if rec := post(viewer, "join example.com now"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected a link from a non-follower to be refused, got %d: %s", rec.Code, rec.Body.String())
 	}
-	if len(adminResponse) != 1 {
-		t.Fatalf("expected one channel for admin, got %d", len(adminResponse))
+	rec = post(owner, "WELCOME TO THE STREAM, rules at example.com/Rules.")
+	var message chatMessageResponse
+	if rec.Code != http.StatusCreated || json.Unmarshal(rec.Body.Bytes(), &message) != nil {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
 	}
-	if adminResponse[0].StreamKey != channel.StreamKey {
-		t.Fatalf("expected admin to receive stream key %s, got %s", channel.StreamKey, adminResponse[0].StreamKey)
+	want := []chat.MessageURL{{Text: "example.com/Rules", URL: "https://example.com/Rules", Host: "example.com"}}
+	if message.Content != "welcome to the stream, rules at example.com/Rules." || !reflect.DeepEqual(message.URLs, want) {
+		t.Fatalf("expected processed content with its links, got %+v", message)
 	}
 }
 
-func TestChannelByIDTrailingSlashMatchesBaseRoute(t *testing.T) {
+func TestChatPostRestrictsSubscriberOnlyChannels(t *testing.T) {
 	handler, store := newTestHandler(t)
-
-	owner, err := store.CreateUser(storage.CreateUserParams{
-		DisplayName: "Owner",
-		Email:       "owner@example.com",
-	})
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
 	if err != nil {
-		t.Fatalf("CreateUser owner: %v", err)
+		t.Fatalf("CreateUser: %v", err)
 	}
-
-	channel, err := store.CreateChannel(owner.ID, "Studio", "gaming", []string{"retro"})
+	subscriber, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Subscriber", Email: "subscriber@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "My Channel", "", nil)
 	if err != nil {
 		t.Fatalf("CreateChannel: %v", err)
 	}
+	if _, err := store.CreateSubscription(storage.CreateSubscriptionParams{ChannelID: channel.ID, UserID: subscriber.ID, Tier: "tier1", Provider: "stripe", Reference: "subs-only", Amount: models.MustParseMoney("4.99"), Currency: "usd", Duration: 100 * 24 * time.Hour}); err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
 
-	baseReq := httptest.NewRequest(http.MethodGet, "/api/channels/"+channel.ID, nil)
-	baseRec := httptest.NewRecorder()
-	handler.ChannelByID(baseRec, baseReq)
-	if baseRec.Code != http.StatusOK {
-		t.Fatalf("expected base route status 200, got %d", baseRec.Code)
+	req := withUser(httptest.NewRequest(http.MethodPatch, "/api/channels/"+channel.ID, strings.NewReader(`{"chatSubscribersOnly": true}`)), owner)
+	rec := httptest.NewRecorder()
+	handler.ChannelByID(rec, req)
+	var updated channelResponse
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &updated) != nil || !updated.ChatSubscribersOnly {
+		t.Fatalf("expected subscriber-only chat to be saved, got %d: %s", rec.Code, rec.Body.String())
 	}
 
-	slashReq := httptest.NewRequest(http.MethodGet, "/api/channels/"+channel.ID+"/", nil)
-	slashRec := httptest.NewRecorder()
-	handler.ChannelByID(slashRec, slashReq)
-	if slashRec.Code != http.StatusOK {
-		t.Fatalf("expected trailing slash status 200, got %d", slashRec.Code)
+	post := func(author models.User) *httptest.ResponseRecorder {
+		t.Helper()
+		body, _ := json.Marshal(map[string]any{"userId": author.ID, "content": "hello"})
+		req := withUser(httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/chat", bytes.NewReader(body)), author)
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		return rec
+	}
+	rec = post(viewer)
+	var failure apiErrorResponse
+	if rec.Code != http.StatusForbidden || json.Unmarshal(rec.Body.Bytes(), &failure) != nil || failure.Error.Code != chat.ErrorCodeSubscribersOnly {
+		t.Fatalf("expected 403 subscribers_only for a viewer, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := post(subscriber); rec.Code != http.StatusCreated {
+		t.Fatalf("expected a subscriber to chat, got %d: %s", rec.Code, rec.Body.String())
 	}
 
-	var basePayload, slashPayload channelPublicResponse
-	if err := json.Unmarshal(baseRec.Body.Bytes(), &basePayload); err != nil {
-		t.Fatalf("decode base response: %v", err)
+	standing := func(user *models.User) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/channels/"+channel.ID+"/subscription", nil)
+		if user != nil {
+			req = withUser(req, *user)
+		}
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		return rec
 	}
-	if err := json.Unmarshal(slashRec.Body.Bytes(), &slashPayload); err != nil {
-		t.Fatalf("decode trailing slash response: %v", err)
+	if rec := standing(nil); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a session, got %d", rec.Code)
 	}
-	if !reflect.DeepEqual(basePayload, slashPayload) {
-		t.Fatalf("expected trailing slash response to match base route\nbase: %#v\nslash: %#v", basePayload, slashPayload)
+	rec = standing(&subscriber)
+	var mine subscriptionStandingResponse
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &mine) != nil {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !mine.Subscribed || mine.Months != 1 || mine.SubscriberTier != 1 || mine.ExpiresAt == nil {
+		t.Fatalf("unexpected standing %+v", mine)
+	}
+	rec = standing(&viewer)
+	mine = subscriptionStandingResponse{}
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &mine) != nil || mine.Subscribed || mine.Months != 0 || mine.SubscriberTier != 0 {
+		t.Fatalf("expected no standing for a viewer, got %d: %s", rec.Code, rec.Body.String())
 	}
 }
 
-func TestChatEndpointsLimit(t *testing.T) {
+func TestChatHistoryResolvesAuthors(t *testing.T) {
 	handler, store := newTestHandler(t)
-	user, err := store.CreateUser(storage.CreateUserParams{
-		DisplayName: "Alice",
-		Email:       "alice@example.com",
-	})
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
 	if err != nil {
 		t.Fatalf("CreateUser: %v", err)
 	}
-	channel, err := store.CreateChannel(user.ID, "My Channel", "", nil)
+	moderator, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Mod", Email: "mod@example.com", Roles: []string{"moderator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Busy", "", nil)
 	if err != nil {
 		t.Fatalf("CreateChannel: %v", err)
 	}
-
-	for i := 0; i < 3; i++ {
-		payload := map[string]interface{}{
-			"userId":  user.ID,
-			"content": "message",
+	avatar := "https://cdn.example.com/mod.png"
+	if _, err := store.UpsertProfile(moderator.ID, storage.ProfileUpdate{AvatarURL: &avatar}); err != nil {
+		t.Fatalf("UpsertProfile: %v", err)
+	}
+	authors := []models.User{owner, moderator}
+	for i := 0; i < 20; i++ {
+		viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: fmt.Sprintf("Viewer %d", i), Email: fmt.Sprintf("viewer%d@example.com", i)})
+		if err != nil {
+			t.Fatalf("CreateUser: %v", err)
 		}
-		body, _ := json.Marshal(payload)
-		req := httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/chat", bytes.NewReader(body))
-		req = withUser(req, user)
-		rec := httptest.NewRecorder()
-		handler.ChannelByID(rec, req)
-		if rec.Code != http.StatusCreated {
-			t.Fatalf("expected chat status 201, got %d", rec.Code)
+		authors = append(authors, viewer)
+	}
+	subscriber := authors[2]
+	if _, err := store.CreateSubscription(storage.CreateSubscriptionParams{ChannelID: channel.ID, UserID: subscriber.ID, Tier: "tier1", Provider: "stripe", Reference: "history-1", Amount: models.MustParseMoney("4.99"), Currency: "usd", Duration: time.Hour}); err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+	for round := 0; round < 2; round++ {
+		for _, author := range authors {
+			if _, err := store.CreateChatMessage(channel.ID, author.ID, "hello", ""); err != nil {
+				t.Fatalf("CreateChatMessage: %v", err)
+			}
 		}
 	}
 
-	req := httptest.NewRequest(http.MethodGet, "/api/channels/"+channel.ID+"/chat?limit=2", nil)
-	req = withUser(req, user)
+	repo := &chatAuthorCountingRepository{Repository: store, deletedUserID: "deleted-user"}
+	handler.Store = repo
 	rec := httptest.NewRecorder()
-	handler.ChannelByID(rec, req)
+	handler.ChannelByID(rec, httptest.NewRequest(http.MethodGet, "/api/channels/"+channel.ID+"/chat", nil))
 	if rec.Code != http.StatusOK {
-		t.Fatalf("expected chat list status 200, got %d", rec.Code)
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
 	}
 	var messages []chatMessageResponse
 	if err := json.Unmarshal(rec.Body.Bytes(), &messages); err != nil {
 		t.Fatalf("decode chat response: %v", err)
 	}
-	if len(messages) != 2 {
-		t.Fatalf("expected 2 messages, got %d", len(messages))
+	if len(messages) != 2*len(authors)+1 {
+		t.Fatalf("expected %d messages, got %d", 2*len(authors)+1, len(messages))
+	}
+	if repo.authorCalls != 1 || repo.userCalls != 0 {
+		t.Fatalf("expected one batched author lookup for the page, got %d author and %d user lookups", repo.authorCalls, repo.userCalls)
+	}
+
+	want := map[string]chat.Author{
+		owner.ID:       {ID: owner.ID, DisplayName: "Owner", Badges: []string{chat.BadgeBroadcaster}},
+		moderator.ID:   {ID: moderator.ID, DisplayName: "Mod", AvatarURL: avatar, Badges: []string{chat.BadgeModerator}},
+		subscriber.ID:  {ID: subscriber.ID, DisplayName: subscriber.DisplayName, Badges: []string{chat.BadgeSubscriber}, SubscriberTier: 1},
+		authors[3].ID:  {ID: authors[3].ID, DisplayName: authors[3].DisplayName, Badges: []string{}},
+		"deleted-user": {ID: "deleted-user", DisplayName: "Deleted user", Badges: []string{}},
+	}
+	for _, message := range messages {
+		if message.Author == nil || message.Author.ID != message.UserID {
+			t.Fatalf("expected message %s to carry its author, got %+v", message.ID, message.Author)
+		}
+		if expected, ok := want[message.UserID]; ok && !reflect.DeepEqual(*message.Author, expected) {
+			t.Fatalf("expected author %+v, got %+v", expected, *message.Author)
+		}
 	}
 }
 
@@ -2422,7 +3968,7 @@ func TestChatRoutesAuthorization(t *testing.T) {
 	if err != nil {
 		t.Fatalf("CreateChannel: %v", err)
 	}
-	message, err := store.CreateChatMessage(channel.ID, owner.ID, "hello world")
+	message, err := store.CreateChatMessage(channel.ID, owner.ID, "hello world", "")
 	if err != nil {
 		t.Fatalf("CreateChatMessage: %v", err)
 	}
@@ -2517,7 +4063,7 @@ func TestProfileEndpoints(t *testing.T) {
 		"featuredChannelId": channel.ID,
 		"topFriends":        []string{friend.ID},
 		"socialLinks":       []map[string]string{{"platform": "YouTube ", "url": "https://youtube.com/streamer "}},
-		"donationAddresses": []map[string]string{{"currency": "eth", "address": "0xabc", "note": "Main"}},
+		"donationAddresses": []map[string]string{{"currency": "eth", "address": "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", "note": "Main"}},
 	}
 	body, _ := json.Marshal(payload)
 	req := httptest.NewRequest(http.MethodPut, "/api/profiles/"+owner.ID, bytes.NewReader(body))
@@ -2688,7 +4234,7 @@ func TestHandleUpsertProfileDonationValidation(t *testing.T) {
 		payload := map[string]interface{}{
 			"donationAddresses": []map[string]string{{
 				"currency": "eth",
-				"address":  "0xabc123",
+				"address":  "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
 				"note":     "primary wallet",
 			}},
 		}
@@ -2717,7 +4263,7 @@ func TestHandleUpsertProfileDonationValidation(t *testing.T) {
 		payload := map[string]interface{}{
 			"donationAddresses": []map[string]string{{
 				"currency": "et1",
-				"address":  "0xabc123",
+				"address":  "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
 			}},
 		}
 		body, _ := json.Marshal(payload)
@@ -2772,7 +4318,9 @@ func TestChatReportsAPI(t *testing.T) {
 	handler.ChatGateway = chat.NewGateway(chat.GatewayConfig{Queue: queue, Store: store})
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	go storage.NewChatWorker(store, queue, nil).Run(ctx)
+	started := make(chan struct{})
+	go storage.NewChatWorker(store, queue, nil).WithStartedChannel(started).Run(ctx)
+	<-started
 
 	// Apply a ban to populate restrictions endpoint.
 	if err := store.ApplyChatEvent(chat.Event{Type: chat.EventTypeModeration, Moderation: &chat.ModerationEvent{Action: chat.ModerationActionBan, ChannelID: channel.ID, ActorID: owner.ID, TargetID: target.ID, Reason: "spam"}, OccurredAt: time.Now().UTC()}); err != nil {
@@ -2973,94 +4521,249 @@ func TestChatModerationRestrictionsOmitExpiredTimeouts(t *testing.T) {
 	}
 }
 
-func TestMonetizationEndpoints(t *testing.T) {
+func TestMonetizationEndpoints(t *testing.T) {
+	handler, store := newTestHandler(t)
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("create owner: %v", err)
+	}
+	supporter, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Supporter", Email: "supporter@example.com"})
+	if err != nil {
+		t.Fatalf("create supporter: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Arena", "gaming", nil)
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+
+	longMessage := strings.Repeat("m", storage.MaxTipMessageLength+1)
+	badTipReq := createTipRequest{Amount: json.Number("10"), Currency: "USD", Provider: "stripe", Message: longMessage}
+	body, _ := json.Marshal(badTipReq)
+	req := httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/monetization/tips", bytes.NewReader(body))
+	req = withUser(req, supporter)
+	rec := httptest.NewRecorder()
+	handler.ChannelByID(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected tip status 400 for long message, got %d", rec.Code)
+	}
+
+	tipReq := createTipRequest{Amount: json.Number("10"), Currency: "USD", Provider: "stripe", Message: "gg"}
+	body, _ = json.Marshal(tipReq)
+	req = httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/monetization/tips", bytes.NewReader(body))
+	req = withUser(req, supporter)
+	rec = httptest.NewRecorder()
+	handler.ChannelByID(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected tip status 201, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/channels/"+channel.ID+"/monetization/tips", nil)
+	req = withUser(req, owner)
+	rec = httptest.NewRecorder()
+	handler.ChannelByID(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected tip list status 200, got %d", rec.Code)
+	}
+
+	subReq := createSubscriptionRequest{Tier: "gold", Provider: "stripe", Amount: json.Number("9.99"), Currency: "usd", DurationDays: 30, AutoRenew: true}
+	body, _ = json.Marshal(subReq)
+	req = httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/monetization/subscriptions", bytes.NewReader(body))
+	req = withUser(req, supporter)
+	rec = httptest.NewRecorder()
+	handler.ChannelByID(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected subscription status 201, got %d", rec.Code)
+	}
+	var subResp subscriptionResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &subResp); err != nil {
+		t.Fatalf("decode subscription: %v", err)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/channels/"+channel.ID+"/monetization/subscriptions", nil)
+	req = withUser(req, owner)
+	rec = httptest.NewRecorder()
+	handler.ChannelByID(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected subscription list status 200, got %d", rec.Code)
+	}
+
+	cancelURL := "/api/channels/" + channel.ID + "/monetization/subscriptions/" + subResp.ID
+	req = httptest.NewRequest(http.MethodDelete, cancelURL, nil)
+	req = withUser(req, supporter)
+	rec = httptest.NewRecorder()
+	handler.ChannelByID(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected cancel status 200, got %d", rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/channels/"+channel.ID+"/monetization/subscriptions?status=all", nil)
+	req = withUser(req, owner)
+	rec = httptest.NewRecorder()
+	handler.ChannelByID(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected subscription history status 200, got %d", rec.Code)
+	}
+	var subs []subscriptionResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &subs); err != nil {
+		t.Fatalf("decode subscriptions response: %v", err)
+	}
+	if len(subs) != 1 || subs[0].Status != "cancelled" {
+		t.Fatalf("expected cancelled subscription, got %+v", subs)
+	}
+}
+
+func TestGiftSubscriptionsToExplicitRecipients(t *testing.T) {
+	handler, store := newTestHandler(t)
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("create owner: %v", err)
+	}
+	gifter, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Gifter", Email: "gifter@example.com"})
+	if err != nil {
+		t.Fatalf("create gifter: %v", err)
+	}
+	first, err := store.CreateUser(storage.CreateUserParams{DisplayName: "First", Email: "first@example.com"})
+	if err != nil {
+		t.Fatalf("create first: %v", err)
+	}
+	second, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Second", Email: "second@example.com"})
+	if err != nil {
+		t.Fatalf("create second: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Arena", "gaming", nil)
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+
+	queue := chat.NewMemoryQueue(4)
+	events := queue.Subscribe()
+	defer events.Close()
+	handler.ChatGateway = chat.NewGateway(chat.GatewayConfig{Queue: queue, Store: store})
+
+	giftReq := giftSubscriptionsRequest{RecipientIDs: []string{first.ID, second.ID}, Tier: "gold", Provider: "stripe", Reference: "order-1", Amount: json.Number("4.99"), Currency: "usd", DurationDays: 30}
+	body, _ := json.Marshal(giftReq)
+	req := httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/monetization/subscriptions/gift", bytes.NewReader(body))
+	req = withUser(req, gifter)
+	rec := httptest.NewRecorder()
+	handler.ChannelByID(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected gift status 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp giftSubscriptionsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode gift response: %v", err)
+	}
+	if resp.Count != 2 || len(resp.Subscriptions) != 2 {
+		t.Fatalf("expected 2 gifted subscriptions, got %+v", resp)
+	}
+	for _, sub := range resp.Subscriptions {
+		if !sub.IsGift || sub.GifterID != gifter.ID {
+			t.Fatalf("expected subscription credited to gifter, got %+v", sub)
+		}
+	}
+
+	select {
+	case evt := <-events.Events():
+		if evt.Type != chat.EventTypeGift || evt.Gift == nil {
+			t.Fatalf("expected gift event, got %+v", evt)
+		}
+		if evt.Gift.GifterID != gifter.ID || evt.Gift.Count != 2 {
+			t.Fatalf("unexpected gift event payload: %+v", evt.Gift)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("timeout waiting for gift event")
+	}
+
+	giftReq.Count = 3
+	giftReq.Reference = "order-2"
+	body, _ = json.Marshal(giftReq)
+	req = httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/monetization/subscriptions/gift", bytes.NewReader(body))
+	req = withUser(req, gifter)
+	rec = httptest.NewRecorder()
+	handler.ChannelByID(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected mismatched count to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestGiftSubscriptionsRandomRecipientsAreDeterministic(t *testing.T) {
 	handler, store := newTestHandler(t)
 	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
 	if err != nil {
 		t.Fatalf("create owner: %v", err)
 	}
-	supporter, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Supporter", Email: "supporter@example.com"})
+	gifter, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Gifter", Email: "gifter@example.com"})
 	if err != nil {
-		t.Fatalf("create supporter: %v", err)
+		t.Fatalf("create gifter: %v", err)
 	}
 	channel, err := store.CreateChannel(owner.ID, "Arena", "gaming", nil)
 	if err != nil {
 		t.Fatalf("create channel: %v", err)
 	}
 
-	longMessage := strings.Repeat("m", storage.MaxTipMessageLength+1)
-	badTipReq := createTipRequest{Amount: json.Number("10"), Currency: "USD", Provider: "stripe", Message: longMessage}
-	body, _ := json.Marshal(badTipReq)
-	req := httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/monetization/tips", bytes.NewReader(body))
-	req = withUser(req, supporter)
-	rec := httptest.NewRecorder()
-	handler.ChannelByID(rec, req)
-	if rec.Code != http.StatusBadRequest {
-		t.Fatalf("expected tip status 400 for long message, got %d", rec.Code)
+	now := time.Now().UTC()
+	postMessage := func(userID string, at time.Time) {
+		t.Helper()
+		id := fmt.Sprintf("msg-%d", at.UnixNano())
+		evt := chat.Event{Type: chat.EventTypeMessage, Message: &chat.MessageEvent{ID: id, ChannelID: channel.ID, UserID: userID, Content: "hi", CreatedAt: at}, OccurredAt: at}
+		if err := store.ApplyChatEvent(evt); err != nil {
+			t.Fatalf("apply chat message: %v", err)
+		}
 	}
-
-	tipReq := createTipRequest{Amount: json.Number("10"), Currency: "USD", Provider: "stripe", Message: "gg"}
-	body, _ = json.Marshal(tipReq)
-	req = httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/monetization/tips", bytes.NewReader(body))
-	req = withUser(req, supporter)
-	rec = httptest.NewRecorder()
-	handler.ChannelByID(rec, req)
-	if rec.Code != http.StatusCreated {
-		t.Fatalf("expected tip status 201, got %d", rec.Code)
+	postMessage(gifter.ID, now.Add(-time.Minute))
+	for i := 0; i < 5; i++ {
+		chatter, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Chatter", Email: fmt.Sprintf("chatter%d@example.com", i)})
+		if err != nil {
+			t.Fatalf("create chatter: %v", err)
+		}
+		postMessage(chatter.ID, now.Add(-time.Duration(i+2)*time.Minute))
+	}
+	stale, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Stale", Email: "stale@example.com"})
+	if err != nil {
+		t.Fatalf("create stale chatter: %v", err)
 	}
+	postMessage(stale.ID, now.Add(-2*time.Hour))
 
-	req = httptest.NewRequest(http.MethodGet, "/api/channels/"+channel.ID+"/monetization/tips", nil)
-	req = withUser(req, owner)
-	rec = httptest.NewRecorder()
-	handler.ChannelByID(rec, req)
-	if rec.Code != http.StatusOK {
-		t.Fatalf("expected tip list status 200, got %d", rec.Code)
+	candidates, err := handler.recentChatters(channel.ID, gifter.ID)
+	if err != nil {
+		t.Fatalf("recentChatters: %v", err)
 	}
+	if len(candidates) != 5 {
+		t.Fatalf("expected 5 recent chatters excluding gifter and stale viewer, got %v", candidates)
+	}
+	expected := (&Handler{Random: rand.New(rand.NewSource(7))}).pickRandom(candidates, 3)
 
-	subReq := createSubscriptionRequest{Tier: "gold", Provider: "stripe", Amount: json.Number("9.99"), Currency: "usd", DurationDays: 30, AutoRenew: true}
-	body, _ = json.Marshal(subReq)
-	req = httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/monetization/subscriptions", bytes.NewReader(body))
-	req = withUser(req, supporter)
-	rec = httptest.NewRecorder()
+	handler.Random = rand.New(rand.NewSource(7))
+	giftReq := giftSubscriptionsRequest{Count: 3, Tier: "gold", Provider: "stripe", Reference: "order-random", Amount: json.Number("4.99"), Currency: "usd", DurationDays: 30}
+	body, _ := json.Marshal(giftReq)
+	req := httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/monetization/subscriptions/gift", bytes.NewReader(body))
+	req = withUser(req, gifter)
+	rec := httptest.NewRecorder()
 	handler.ChannelByID(rec, req)
 	if rec.Code != http.StatusCreated {
-		t.Fatalf("expected subscription status 201, got %d", rec.Code)
+		t.Fatalf("expected gift status 201, got %d: %s", rec.Code, rec.Body.String())
 	}
-	var subResp subscriptionResponse
-	if err := json.Unmarshal(rec.Body.Bytes(), &subResp); err != nil {
-		t.Fatalf("decode subscription: %v", err)
+	var resp giftSubscriptionsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode gift response: %v", err)
 	}
-
-	req = httptest.NewRequest(http.MethodGet, "/api/channels/"+channel.ID+"/monetization/subscriptions", nil)
-	req = withUser(req, owner)
-	rec = httptest.NewRecorder()
-	handler.ChannelByID(rec, req)
-	if rec.Code != http.StatusOK {
-		t.Fatalf("expected subscription list status 200, got %d", rec.Code)
+	got := make([]string, 0, len(resp.Subscriptions))
+	for _, sub := range resp.Subscriptions {
+		got = append(got, sub.UserID)
 	}
-
-	cancelURL := "/api/channels/" + channel.ID + "/monetization/subscriptions/" + subResp.ID
-	req = httptest.NewRequest(http.MethodDelete, cancelURL, nil)
-	req = withUser(req, supporter)
-	rec = httptest.NewRecorder()
-	handler.ChannelByID(rec, req)
-	if rec.Code != http.StatusOK {
-		t.Fatalf("expected cancel status 200, got %d", rec.Code)
+	if !reflect.DeepEqual(got, expected) {
+		t.Fatalf("expected seeded selection %v, got %v", expected, got)
 	}
 
-	req = httptest.NewRequest(http.MethodGet, "/api/channels/"+channel.ID+"/monetization/subscriptions?status=all", nil)
-	req = withUser(req, owner)
+	giftReq.Count = 6
+	giftReq.Reference = "order-too-many"
+	body, _ = json.Marshal(giftReq)
+	req = httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/monetization/subscriptions/gift", bytes.NewReader(body))
+	req = withUser(req, gifter)
 	rec = httptest.NewRecorder()
 	handler.ChannelByID(rec, req)
-	if rec.Code != http.StatusOK {
-		t.Fatalf("expected subscription history status 200, got %d", rec.Code)
-	}
-	var subs []subscriptionResponse
-	if err := json.Unmarshal(rec.Body.Bytes(), &subs); err != nil {
-		t.Fatalf("decode subscriptions response: %v", err)
-	}
-	if len(subs) != 1 || subs[0].Status != "cancelled" {
-		t.Fatalf("expected cancelled subscription, got %+v", subs)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected gift larger than the chatter pool to fail, got %d", rec.Code)
 	}
 }
 
@@ -3155,7 +4858,7 @@ func TestChannelPlaybackIncludesSubscriptionState(t *testing.T) {
 	if err != nil {
 		t.Fatalf("CreateUser owner: %v", err)
 	}
-	donation := []models.CryptoAddress{{Currency: "eth", Address: "0xabc123", Note: "Main"}}
+	donation := []models.CryptoAddress{{Currency: "eth", Address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", Note: "Main"}}
 	if _, err := store.UpsertProfile(owner.ID, storage.ProfileUpdate{DonationAddresses: &donation}); err != nil {
 		t.Fatalf("UpsertProfile donation: %v", err)
 	}
@@ -3223,8 +4926,8 @@ func TestChannelPlaybackIncludesSubscriptionState(t *testing.T) {
 	if donationResp.Currency != "ETH" {
 		t.Fatalf("expected donation currency ETH, got %s", donationResp.Currency)
 	}
-	if donationResp.Address != "0xabc123" {
-		t.Fatalf("expected donation address 0xabc123, got %s", donationResp.Address)
+	if donationResp.Address != "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed" {
+		t.Fatalf("expected donation address 0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed, got %s", donationResp.Address)
 	}
 	if donationResp.Note != "Main" {
 		t.Fatalf("expected donation note Main, got %s", donationResp.Note)
@@ -3345,7 +5048,7 @@ func TestModerationQueueLifecycle(t *testing.T) {
 	if err != nil {
 		t.Fatalf("CreateChannel: %v", err)
 	}
-	message, err := store.CreateChatMessage(channel.ID, target.ID, "spam message")
+	message, err := store.CreateChatMessage(channel.ID, target.ID, "spam message", "")
 	if err != nil {
 		t.Fatalf("CreateChatMessage: %v", err)
 	}
@@ -3446,10 +5149,10 @@ func TestAnalyticsOverview(t *testing.T) {
 	if err := store.FollowChannel(viewer.ID, channel.ID); err != nil {
 		t.Fatalf("FollowChannel: %v", err)
 	}
-	if _, err := store.CreateChatMessage(channel.ID, viewer.ID, "Hello world"); err != nil {
+	if _, err := store.CreateChatMessage(channel.ID, viewer.ID, "Hello world", ""); err != nil {
 		t.Fatalf("CreateChatMessage: %v", err)
 	}
-	if _, err := store.CreateChatMessage(channel.ID, viewer.ID, "Another message"); err != nil {
+	if _, err := store.CreateChatMessage(channel.ID, viewer.ID, "Another message", ""); err != nil {
 		t.Fatalf("CreateChatMessage second: %v", err)
 	}
 
@@ -3608,3 +5311,380 @@ func TestSRSHookRejectsUnknownStream(t *testing.T) {
 		t.Fatalf("expected status 404, got %d", rec.Code)
 	}
 }
+
+func TestSRSHookPublishRequiresStreamKey(t *testing.T) {
+	handler, store := newTestHandler(t)
+	handler.SRSHookToken = "secret"
+	creator, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Streamer", Email: "keys@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("create user: %v", err)
+	}
+	channel, err := store.CreateChannel(creator.ID, "Keyed", "gaming", nil)
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+
+	for _, stream := range []string{channel.ID, channel.StreamKey + "x", channel.StreamKey[:len(channel.StreamKey)-1]} {
+		payload := srsHookRequest{Action: "on_publish", Stream: stream}
+		body, _ := json.Marshal(payload)
+		req := httptest.NewRequest(http.MethodPost, "/api/ingest/srs-hook?token=secret", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+		handler.SRSHook(rec, req)
+		if rec.Code != http.StatusNotFound {
+			t.Fatalf("expected publish with %q to be rejected with 404, got %d", stream, rec.Code)
+		}
+	}
+	if _, ok := store.CurrentStreamSession(channel.ID); ok {
+		t.Fatal("expected no stream session after rejected publishes")
+	}
+}
+
+func seedRecordingChat(t *testing.T, store *storage.Storage) (models.User, models.Channel, models.Recording, time.Time) {
+	t.Helper()
+	owner, err := store.CreateUser(storage.CreateUserParams{
+		DisplayName: "Owner",
+		Email:       "owner@example.com",
+		Roles:       []string{"creator"},
+	})
+	if err != nil {
+		t.Fatalf("CreateUser owner: %v", err)
+	}
+	viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser viewer: %v", err)
+	}
+	troll, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Troll", Email: "troll@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser troll: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Replay", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	session, err := store.StartStream(channel.ID, []string{"720p"})
+	if err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	start := session.StartedAt
+	seed := []struct {
+		id     string
+		userID string
+		offset time.Duration
+	}{
+		{"msg-early", viewer.ID, 5 * time.Second},
+		{"msg-1", viewer.ID, 20 * time.Second},
+		{"msg-troll", troll.ID, 25 * time.Second},
+		{"msg-deleted", viewer.ID, 30 * time.Second},
+		{"msg-2", viewer.ID, 40 * time.Second},
+		{"msg-late", viewer.ID, 90 * time.Second},
+	}
+	for _, msg := range seed {
+		if err := store.ApplyChatEvent(chat.Event{
+			Type: chat.EventTypeMessage,
+			Message: &chat.MessageEvent{
+				ID:        msg.id,
+				ChannelID: channel.ID,
+				UserID:    msg.userID,
+				Content:   msg.id,
+				CreatedAt: start.Add(msg.offset),
+			},
+		}); err != nil {
+			t.Fatalf("ApplyChatEvent %s: %v", msg.id, err)
+		}
+	}
+	if err := store.DeleteChatMessage(channel.ID, "msg-deleted", owner.ID); err != nil {
+		t.Fatalf("DeleteChatMessage: %v", err)
+	}
+	if err := store.ApplyChatEvent(chat.Event{
+		Type: chat.EventTypeModeration,
+		Moderation: &chat.ModerationEvent{
+			Action:    chat.ModerationActionBan,
+			ChannelID: channel.ID,
+			ActorID:   owner.ID,
+			TargetID:  troll.ID,
+		},
+		OccurredAt: start.Add(time.Minute),
+	}); err != nil {
+		t.Fatalf("ApplyChatEvent ban: %v", err)
+	}
+	if _, err := store.StopStream(channel.ID, 10); err != nil {
+		t.Fatalf("StopStream: %v", err)
+	}
+	recordings, err := store.ListRecordings(channel.ID, true)
+	if err != nil || len(recordings) == 0 {
+		t.Fatalf("ListRecordings: %v (count %d)", err, len(recordings))
+	}
+	return owner, channel, recordings[0], start
+}
+
+func TestRecordingChatReplayWindow(t *testing.T) {
+	handler, store := newTestHandler(t)
+	_, _, recording, start := seedRecordingChat(t, store)
+	if _, err := store.PublishRecording(recording.ID); err != nil {
+		t.Fatalf("PublishRecording: %v", err)
+	}
+	handler.Now = func() time.Time { return start.Add(time.Hour) }
+
+	req := httptest.NewRequest(http.MethodGet, "/api/recordings/"+recording.ID+"/chat?fromSeconds=15&toSeconds=60", nil)
+	rec := httptest.NewRecorder()
+	handler.RecordingByID(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp recordingChatResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(resp.Messages) != 2 || resp.Messages[0].ID != "msg-1" || resp.Messages[1].ID != "msg-2" {
+		t.Fatalf("expected msg-1 and msg-2 in window, got %+v", resp.Messages)
+	}
+	if resp.Messages[0].OffsetSeconds != 20 || resp.Messages[1].OffsetSeconds != 40 {
+		t.Fatalf("expected offsets 20 and 40, got %v and %v", resp.Messages[0].OffsetSeconds, resp.Messages[1].OffsetSeconds)
+	}
+	if resp.NextCursor != "" {
+		t.Fatalf("expected no further pages, got cursor %q", resp.NextCursor)
+	}
+
+	etag := rec.Header().Get("ETag")
+	if etag == "" {
+		t.Fatal("expected ETag on historical window")
+	}
+	if rec.Header().Get("Last-Modified") == "" {
+		t.Fatal("expected Last-Modified on historical window")
+	}
+	if cc := rec.Header().Get("Cache-Control"); !strings.HasPrefix(cc, "public") {
+		t.Fatalf("expected public cache control, got %q", cc)
+	}
+
+	cachedReq := httptest.NewRequest(http.MethodGet, "/api/recordings/"+recording.ID+"/chat?fromSeconds=15&toSeconds=60", nil)
+	cachedReq.Header.Set("If-None-Match", etag)
+	cachedRec := httptest.NewRecorder()
+	handler.RecordingByID(cachedRec, cachedReq)
+	if cachedRec.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 for matching ETag, got %d", cachedRec.Code)
+	}
+
+	pageReq := httptest.NewRequest(http.MethodGet, "/api/recordings/"+recording.ID+"/chat?fromSeconds=0&toSeconds=60&limit=1", nil)
+	pageRec := httptest.NewRecorder()
+	handler.RecordingByID(pageRec, pageReq)
+	var page recordingChatResponse
+	if err := json.Unmarshal(pageRec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("decode page: %v", err)
+	}
+	if len(page.Messages) != 1 || page.Messages[0].ID != "msg-early" || page.NextCursor == "" {
+		t.Fatalf("expected first page with cursor, got %+v", page)
+	}
+	nextReq := httptest.NewRequest(http.MethodGet, "/api/recordings/"+recording.ID+"/chat?fromSeconds=0&toSeconds=60&limit=1&cursor="+page.NextCursor, nil)
+	nextRec := httptest.NewRecorder()
+	handler.RecordingByID(nextRec, nextReq)
+	var next recordingChatResponse
+	if err := json.Unmarshal(nextRec.Body.Bytes(), &next); err != nil {
+		t.Fatalf("decode next page: %v", err)
+	}
+	if len(next.Messages) != 1 || next.Messages[0].ID != "msg-1" {
+		t.Fatalf("expected second page to contain msg-1, got %+v", next.Messages)
+	}
+
+	handler.Now = func() time.Time { return start.Add(30 * time.Second) }
+	liveRec := httptest.NewRecorder()
+	handler.RecordingByID(liveRec, httptest.NewRequest(http.MethodGet, "/api/recordings/"+recording.ID+"/chat?fromSeconds=15&toSeconds=60", nil))
+	if liveRec.Header().Get("ETag") != "" || liveRec.Header().Get("Cache-Control") != "no-cache" {
+		t.Fatalf("expected open window to be uncached, got headers %v", liveRec.Header())
+	}
+
+	badRec := httptest.NewRecorder()
+	handler.RecordingByID(badRec, httptest.NewRequest(http.MethodGet, "/api/recordings/"+recording.ID+"/chat?fromSeconds=60&toSeconds=15", nil))
+	if badRec.Code != http.StatusBadRequest {
+		t.Fatalf("expected inverted window to be rejected, got %d", badRec.Code)
+	}
+}
+
+func TestRecordingChatReplayRespectsVisibility(t *testing.T) {
+	handler, store := newTestHandler(t)
+	owner, _, recording, start := seedRecordingChat(t, store)
+	handler.Now = func() time.Time { return start.Add(time.Hour) }
+	path := "/api/recordings/" + recording.ID + "/chat?fromSeconds=0&toSeconds=60"
+
+	anonRec := httptest.NewRecorder()
+	handler.RecordingByID(anonRec, httptest.NewRequest(http.MethodGet, path, nil))
+	if anonRec.Code != http.StatusForbidden {
+		t.Fatalf("expected unpublished replay to be forbidden, got %d", anonRec.Code)
+	}
+
+	ownerRec := httptest.NewRecorder()
+	handler.RecordingByID(ownerRec, withUser(httptest.NewRequest(http.MethodGet, path, nil), owner))
+	if ownerRec.Code != http.StatusOK {
+		t.Fatalf("expected owner to read unpublished replay, got %d: %s", ownerRec.Code, ownerRec.Body.String())
+	}
+	if cc := ownerRec.Header().Get("Cache-Control"); !strings.HasPrefix(cc, "private") {
+		t.Fatalf("expected private cache control for unpublished recording, got %q", cc)
+	}
+}
+
+func TestCurrentUserBirthDateIsSetOnce(t *testing.T) {
+	handler, store := newTestHandler(t)
+	user, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	patch := func(body string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(http.MethodPatch, "/api/users/me", strings.NewReader(body)), user)
+		rec := httptest.NewRecorder()
+		handler.UserByID(rec, req)
+		return rec
+	}
+
+	if rec := patch(`{"birthDate":"15/06/1990"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a malformed date, got %d", rec.Code)
+	}
+	if rec := patch(`{"birthDate":"2999-01-01"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a future date, got %d", rec.Code)
+	}
+	rec := patch(`{"birthDate":"1990-06-15"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var confirmed userResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &confirmed); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if confirmed.BirthDate != "1990-06-15" || confirmed.AgeConfirmedAt == nil {
+		t.Fatalf("expected confirmed birth date, got %+v", confirmed)
+	}
+
+	user, _ = store.GetUser(user.ID)
+	if rec := patch(`{"birthDate":"1980-01-01"}`); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 when changing a confirmed birth date, got %d", rec.Code)
+	}
+
+	req := withUser(httptest.NewRequest(http.MethodGet, "/api/users/me", nil), user)
+	rec = httptest.NewRecorder()
+	handler.UserByID(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"birthDate":"1990-06-15"`) {
+		t.Fatalf("expected current user with birth date, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestNotificationPreferencesEndpoint(t *testing.T) {
+	handler, store := newTestHandler(t)
+	user, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	serve := func(method, body string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(method, "/api/users/me/notification-preferences", strings.NewReader(body)), user)
+		rec := httptest.NewRecorder()
+		handler.UserByID(rec, req)
+		return rec
+	}
+	decode := func(rec *httptest.ResponseRecorder) notificationPreferencesResponse {
+		t.Helper()
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var prefs notificationPreferencesResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &prefs); err != nil {
+			t.Fatalf("decode preferences: %v", err)
+		}
+		return prefs
+	}
+
+	defaults := decode(serve(http.MethodGet, ""))
+	if !defaults.FollowedLive.InApp || defaults.FollowedLive.Email || !defaults.Account.Email || defaults.UpdatedAt != nil {
+		t.Fatalf("expected default preferences, got %+v", defaults)
+	}
+
+	updated := decode(serve(http.MethodPatch, `{"mentions":{"email":true},"followedLive":{"inApp":false}}`))
+	if !updated.Mentions.InApp || !updated.Mentions.Email {
+		t.Fatalf("expected mentions email enabled without touching in-app, got %+v", updated.Mentions)
+	}
+	if updated.FollowedLive.InApp || updated.Moderation != defaults.Moderation || updated.Account != defaults.Account {
+		t.Fatalf("expected only the named settings to change, got %+v", updated)
+	}
+	prefs, err := store.GetNotificationPreferences(user.ID)
+	if err != nil {
+		t.Fatalf("GetNotificationPreferences: %v", err)
+	}
+	if prefs.Allows(models.NotificationCategoryFollowedLive, models.NotificationDeliveryInApp) {
+		t.Fatal("expected go-live notifications to be refused immediately after disabling them")
+	}
+
+	for _, body := range []string{`{"marketing":{"email":true}}`, `{"mentions":{"sms":true}}`} {
+		rec := serve(http.MethodPatch, body)
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %s, got %d", body, rec.Code)
+		}
+		if resp := decodeAPIError(t, rec.Body.Bytes()); resp.Error.Code != "validation_failed" || !strings.Contains(resp.Error.Message, "unknown field") {
+			t.Fatalf("expected unknown field validation error for %s, got %+v", body, resp)
+		}
+	}
+	if again := decode(serve(http.MethodGet, "")); again.Mentions != updated.Mentions || again.FollowedLive != updated.FollowedLive {
+		t.Fatalf("expected rejected patches to leave preferences untouched, got %+v", again)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users/me/notification-preferences", nil)
+	rec := httptest.NewRecorder()
+	handler.UserByID(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a session, got %d", rec.Code)
+	}
+}
+
+func TestChannelFollowersPaginatesNewestFirst(t *testing.T) {
+	handler, store := newTestHandler(t)
+
+	creator, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Creator", Email: "creator@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("create creator: %v", err)
+	}
+	channel, err := store.CreateChannel(creator.ID, "Studio", "gaming", nil)
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	var followers []models.User
+	for i := 0; i < 3; i++ {
+		follower, err := store.CreateUser(storage.CreateUserParams{DisplayName: fmt.Sprintf("Fan %d", i), Email: fmt.Sprintf("fan%d@example.com", i)})
+		if err != nil {
+			t.Fatalf("create follower: %v", err)
+		}
+		if err := store.FollowChannel(follower.ID, channel.ID); err != nil {
+			t.Fatalf("follow channel: %v", err)
+		}
+		followers = append(followers, follower)
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	list := func(query string) (*httptest.ResponseRecorder, []channelFollowerResponse, pageResponse) {
+		req := withUser(httptest.NewRequest(http.MethodGet, "/api/channels/"+channel.ID+"/followers"+query, nil), creator)
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		var items []channelFollowerResponse
+		page := pageResponse{Items: &items}
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+		}
+		return rec, items, page
+	}
+
+	rec, items, page := list("?perPage=2")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if page.Total != 3 || page.Page != 1 || page.PerPage != 2 || len(items) != 2 {
+		t.Fatalf("unexpected first page %+v with %d items", page, len(items))
+	}
+	if items[0].UserID != followers[2].ID || items[1].UserID != followers[1].ID || items[0].DisplayName != "Fan 2" || items[0].FollowedAt == "" {
+		t.Fatalf("expected newest followers first, got %+v", items)
+	}
+
+	_, items, _ = list("?page=2&perPage=2")
+	if len(items) != 1 || items[0].UserID != followers[0].ID {
+		t.Fatalf("unexpected second page %+v", items)
+	}
+
+	if rec, _, _ := list("?perPage=0"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid perPage, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/auth"
+	"bitriver-live/internal/authz"
+	"bitriver-live/internal/models"
+)
+
+// impersonationResponse tells the UI who is really behind an impersonation
+// session so it can show a banner and a way back.
+type impersonationResponse struct {
+	AdminID          string `json:"adminId"`
+	AdminDisplayName string `json:"adminDisplayName"`
+	ExpiresAt        string `json:"expiresAt"`
+}
+
+func newImpersonationResponse(admin models.User, session auth.SessionRecord) *impersonationResponse {
+	return &impersonationResponse{
+		AdminID:          admin.ID,
+		AdminDisplayName: admin.DisplayName,
+		ExpiresAt:        session.ExpiresAt.UTC().Format(time.RFC3339Nano),
+	}
+}
+
+// canImpersonate reports whether the user may act as other users. Holders of
+// users.manage can impersonate, and for the same reason can never be
+// impersonated themselves.
+func canImpersonate(user models.User) bool {
+	return authz.Has(user, authz.UsersManage)
+}
+
+// Impersonator resolves the administrator behind an impersonation session and
+// confirms they are still allowed to impersonate.
+func (h *Handler) Impersonator(session auth.SessionRecord) (models.User, error) {
+	admin, ok := h.Store.GetUser(session.ImpersonatorID)
+	if !ok || admin.Deactivated() || !canImpersonate(admin) {
+		return models.User{}, fmt.Errorf("impersonation is no longer permitted")
+	}
+	return admin, nil
+}
+
+// AdminUserByID serves /api/admin/users/{id}/impersonate. POST issues an
+// impersonation session for the user in a cookie of its own; the
+// administrator's session cookie is left in place and takes over again when
+// the impersonation ends via DELETE /api/auth/session or expires.
+// /api/admin/users/{id}/stream-limit is served by adminUserStreamLimit.
+func (h *Handler) AdminUserByID(w http.ResponseWriter, r *http.Request) {
+	path := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/users/"), "/")
+	parts := strings.Split(path, "/")
+	if len(parts) == 2 && parts[0] != "" && parts[1] == "stream-limit" {
+		h.adminUserStreamLimit(parts[0], w, r)
+		return
+	}
+	if len(parts) != 2 || parts[0] == "" || parts[1] != "impersonate" {
+		WriteError(w, http.StatusNotFound, fmt.Errorf("unknown admin user action"))
+		return
+	}
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+	admin, ok := h.requirePermission(w, r, authz.UsersManage)
+	if !ok {
+		return
+	}
+	if _, usingToken := APITokenFromContext(r.Context()); usingToken {
+		WriteError(w, http.StatusForbidden, fmt.Errorf("impersonation requires a signed-in session"))
+		return
+	}
+	target, exists := h.Store.GetUser(parts[0])
+	if !exists {
+		WriteError(w, http.StatusNotFound, fmt.Errorf("user %s not found", parts[0]))
+		return
+	}
+	if canImpersonate(target) {
+		WriteError(w, http.StatusForbidden, fmt.Errorf("administrators cannot be impersonated"))
+		return
+	}
+	if target.Deactivated() {
+		WriteError(w, http.StatusConflict, fmt.Errorf("user %s is deactivated", target.ID))
+		return
+	}
+
+	token, expiresAt, err := h.sessionManager().CreateImpersonation(admin.ID, target.ID, auth.DefaultImpersonationTTL)
+	if err != nil {
+		WriteRequestError(w, err)
+		return
+	}
+	setAuthCookie(w, r, impersonationCookieName, token, expiresAt, h.sessionCookiePolicy())
+	h.auditLogger().Info("audit", "action", "user.impersonate", "actor_id", admin.ID, "user_id", target.ID, "expires_at", expiresAt.UTC().Format(time.RFC3339))
+
+	resp := newAuthResponse(target, expiresAt)
+	resp.Impersonation = newImpersonationResponse(admin, auth.SessionRecord{ExpiresAt: expiresAt})
+	WriteJSON(w, http.StatusCreated, resp)
+}
+
+// endImpersonation revokes the impersonation session carried by the request
+// and drops its cookie.
+func (h *Handler) endImpersonation(w http.ResponseWriter, r *http.Request, token string) {
+	session, ok, err := h.sessionManager().ValidateSession(token)
+	if err == nil && ok && session.Impersonated() {
+		h.auditLogger().Info("audit", "action", "user.impersonate_end", "actor_id", session.ImpersonatorID, "user_id", session.UserID)
+	}
+	if err := h.sessionManager().Revoke(token); err != nil {
+		writeStorageError(w, http.StatusInternalServerError, err)
+		return
+	}
+	h.ClearImpersonationCookie(w, r)
+	w.WriteHeader(http.StatusNoContent)
+}
@@ -0,0 +1,173 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"bitriver-live/internal/authz"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+func impersonateRequest(targetID string, actor models.User) *http.Request {
+	return withUser(httptest.NewRequest(http.MethodPost, "/api/admin/users/"+targetID+"/impersonate", nil), actor)
+}
+
+func withAPIToken(req *http.Request) *http.Request {
+	return req.WithContext(ContextWithAPIToken(req.Context(), models.APIToken{ID: "token"}))
+}
+
+func TestAdminImpersonationIssuance(t *testing.T) {
+	handler, store := newTestHandler(t)
+	admin, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Admin", Email: "admin@example.com", Roles: []string{authz.RoleAdmin}})
+	if err != nil {
+		t.Fatalf("CreateUser admin: %v", err)
+	}
+	otherAdmin, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Other", Email: "other@example.com", Roles: []string{authz.RoleAdmin}})
+	if err != nil {
+		t.Fatalf("CreateUser other admin: %v", err)
+	}
+	viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser viewer: %v", err)
+	}
+	moderator, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Mod", Email: "mod@example.com", Roles: []string{authz.RoleModerator}})
+	if err != nil {
+		t.Fatalf("CreateUser moderator: %v", err)
+	}
+
+	rejected := []struct {
+		name   string
+		req    *http.Request
+		status int
+	}{
+		{name: "non-admin", req: impersonateRequest(viewer.ID, moderator), status: http.StatusForbidden},
+		{name: "other admin", req: impersonateRequest(otherAdmin.ID, admin), status: http.StatusForbidden},
+		{name: "self", req: impersonateRequest(admin.ID, admin), status: http.StatusForbidden},
+		{name: "access token", req: withAPIToken(impersonateRequest(viewer.ID, admin)), status: http.StatusForbidden},
+		{name: "unknown user", req: impersonateRequest("missing", admin), status: http.StatusNotFound},
+	}
+	for _, tc := range rejected {
+		rec := httptest.NewRecorder()
+		handler.AdminUserByID(rec, tc.req)
+		if rec.Code != tc.status {
+			t.Fatalf("%s: expected %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+		for _, cookie := range rec.Result().Cookies() {
+			if cookie.Name == impersonationCookieName {
+				t.Fatalf("%s: expected no impersonation cookie", tc.name)
+			}
+		}
+	}
+
+	rec := httptest.NewRecorder()
+	handler.AdminUserByID(rec, impersonateRequest(viewer.ID, admin))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp authResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.User.ID != viewer.ID || resp.Impersonation == nil || resp.Impersonation.AdminID != admin.ID {
+		t.Fatalf("expected an impersonation of the viewer by the admin, got %+v", resp)
+	}
+	cookie := findCookie(t, rec.Result().Cookies(), impersonationCookieName)
+	if !cookie.HttpOnly || cookie.Value == "" {
+		t.Fatalf("expected an HttpOnly impersonation cookie, got %+v", cookie)
+	}
+	for _, set := range rec.Result().Cookies() {
+		if set.Name == sessionCookieName {
+			t.Fatal("expected the admin's session cookie to be left alone")
+		}
+	}
+	session, ok, err := handler.sessionManager().ValidateSession(cookie.Value)
+	if err != nil || !ok {
+		t.Fatalf("expected the impersonation session to validate, ok=%v err=%v", ok, err)
+	}
+	if session.UserID != viewer.ID || session.ImpersonatorID != admin.ID {
+		t.Fatalf("expected the session bound to both identities, got %+v", session)
+	}
+}
+
+func TestSessionEndpointDisclosesAndEndsImpersonation(t *testing.T) {
+	handler, store := newTestHandler(t)
+	admin, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Admin", Email: "admin@example.com", Roles: []string{authz.RoleAdmin}})
+	if err != nil {
+		t.Fatalf("CreateUser admin: %v", err)
+	}
+	viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser viewer: %v", err)
+	}
+	adminToken, _, err := handler.sessionManager().Create(admin.ID)
+	if err != nil {
+		t.Fatalf("Create session: %v", err)
+	}
+	impersonationToken, _, err := handler.sessionManager().CreateImpersonation(admin.ID, viewer.ID, 0)
+	if err != nil {
+		t.Fatalf("CreateImpersonation: %v", err)
+	}
+	withCookies := func(method string) *http.Request {
+		req := httptest.NewRequest(method, "/api/auth/session", nil)
+		req.AddCookie(&http.Cookie{Name: sessionCookieName, Value: adminToken})
+		req.AddCookie(&http.Cookie{Name: impersonationCookieName, Value: impersonationToken})
+		return req
+	}
+
+	rec := httptest.NewRecorder()
+	handler.Session(rec, withCookies(http.MethodGet))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp authResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.User.ID != viewer.ID || resp.Impersonation == nil || resp.Impersonation.AdminID != admin.ID || resp.Impersonation.AdminDisplayName != "Admin" {
+		t.Fatalf("expected the session to disclose the impersonation, got %+v", resp)
+	}
+	if len(rec.Result().Cookies()) != 0 {
+		t.Fatal("expected impersonation sessions not to refresh any cookie")
+	}
+
+	rec = httptest.NewRecorder()
+	handler.Session(rec, withCookies(http.MethodDelete))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if cookie := findCookie(t, rec.Result().Cookies(), impersonationCookieName); cookie.MaxAge != -1 {
+		t.Fatalf("expected the impersonation cookie to be cleared, got %+v", cookie)
+	}
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == sessionCookieName {
+			t.Fatal("expected ending the impersonation to keep the admin signed in")
+		}
+	}
+	if _, _, ok, _ := handler.sessionManager().Validate(impersonationToken); ok {
+		t.Fatal("expected the impersonation session to be revoked")
+	}
+	if userID, _, ok, _ := handler.sessionManager().Validate(adminToken); !ok || userID != admin.ID {
+		t.Fatal("expected the admin's own session to survive")
+	}
+
+	// An admin who loses the role can no longer use sessions they started.
+	demotedToken, _, err := handler.sessionManager().CreateImpersonation(admin.ID, viewer.ID, 0)
+	if err != nil {
+		t.Fatalf("CreateImpersonation: %v", err)
+	}
+	roles := []string{}
+	if _, err := store.UpdateUser(admin.ID, storage.UserUpdate{Roles: &roles}); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/auth/session", nil)
+	req.AddCookie(&http.Cookie{Name: impersonationCookieName, Value: demotedToken})
+	if _, _, err := handler.AuthenticateSession(req); err == nil {
+		t.Fatal("expected a demoted admin's impersonation to be refused")
+	}
+	if _, _, ok, _ := handler.sessionManager().Validate(demotedToken); ok {
+		t.Fatal("expected the refused impersonation session to be revoked")
+	}
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+// Stream states reported to players. Starting covers both a session whose
+// ingest is still booting and one provisioned but not yet receiving media.
+const (
+	streamStateOffline     = "offline"
+	streamStateStarting    = "starting"
+	streamStateLive        = "live"
+	streamStateInterrupted = "interrupted"
+)
+
+// streamState says what a player should show for channel, given its current
+// session when it has one. Media is only tracked once ingest events are
+// configured; until then a live channel is taken to be receiving media.
+func (h *Handler) streamState(channel models.Channel, session models.StreamSession, hasSession bool) string {
+	switch channel.LiveState {
+	case "live":
+	case "starting":
+		return streamStateStarting
+	default:
+		return streamStateOffline
+	}
+	if !hasSession || strings.TrimSpace(h.IngestEventsToken) == "" {
+		return streamStateLive
+	}
+	switch {
+	case session.InterruptedAt != nil:
+		return streamStateInterrupted
+	case session.ReceivingMedia:
+		return streamStateLive
+	default:
+		return streamStateStarting
+	}
+}
+
+// ingestEventRequest identifies the stream an event is about either by its
+// stream key or by the channel and session the ingest backend was booted
+// with.
+type ingestEventRequest struct {
+	Event     string `json:"event"`
+	StreamKey string `json:"streamKey"`
+	ChannelID string `json:"channelId"`
+	SessionID string `json:"sessionId"`
+	// ClientIP is the encoder's address on publish events. A publish from
+	// an address other than the one the session is publishing from is
+	// refused.
+	ClientIP string `json:"clientIp,omitempty"`
+}
+
+type ingestEventResponse struct {
+	// Code is always 0, which SRS http_hooks require of a successful
+	// answer.
+	Code int `json:"code"`
+	// Status is "ok" when the event updated the session and "ignored" when
+	// it referred to a session that is no longer current.
+	Status      string `json:"status"`
+	ChannelID   string `json:"channelId"`
+	SessionID   string `json:"sessionId,omitempty"`
+	StreamState string `json:"streamState"`
+}
+
+// ingestEventReceiving maps an ingest event to whether media is arriving.
+func ingestEventReceiving(event string) (bool, bool) {
+	switch normalizeSRSAction(event) {
+	case "publish":
+		return true, true
+	case "unpublish", "idle":
+		return false, true
+	}
+	return false, false
+}
+
+// IngestEvents serves POST /api/internal/ingest/events, which SRS and OME
+// hook configurations call when media starts or stops arriving for a
+// session. It answers 404 while no ingest events token is configured.
+func (h *Handler) IngestEvents(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+	if strings.TrimSpace(h.IngestEventsToken) == "" {
+		WriteError(w, http.StatusNotFound, fmt.Errorf("ingest events are not enabled"))
+		return
+	}
+	if !sharedTokenAuthorized(h.IngestEventsToken, r) {
+		h.logger().Warn("ingest event rejected token", "path", r.URL.Path, "remote", r.RemoteAddr)
+		WriteError(w, http.StatusUnauthorized, fmt.Errorf("unauthorized"))
+		return
+	}
+
+	var req ingestEventRequest
+	if err := DecodeJSONAllowUnknown(r, &req); err != nil {
+		WriteDecodeError(w, err)
+		return
+	}
+	receiving, ok := ingestEventReceiving(req.Event)
+	if !ok {
+		WriteRequestError(w, ValidationError(fmt.Sprintf("unknown event %q; expected publish, unpublish, or idle", strings.TrimSpace(req.Event))))
+		return
+	}
+	sessionID := strings.TrimSpace(req.SessionID)
+	var channel models.Channel
+	switch {
+	case strings.TrimSpace(req.StreamKey) != "":
+		channel, ok = h.channelForStream(req.StreamKey, false)
+	case strings.TrimSpace(req.ChannelID) != "" && sessionID != "":
+		channel, ok = h.Store.GetChannel(strings.TrimSpace(req.ChannelID))
+	default:
+		WriteRequestError(w, ValidationError("streamKey, or channelId and sessionId, are required"))
+		return
+	}
+	if !ok {
+		WriteError(w, http.StatusNotFound, fmt.Errorf("stream not recognized"))
+		return
+	}
+
+	current, live := h.Store.CurrentStreamSession(channel.ID)
+	if !live || (sessionID != "" && current.ID != sessionID) {
+		// Events can trail the session they belong to, such as an
+		// unpublish arriving after the stream was stopped.
+		WriteJSON(w, http.StatusOK, ingestEventResponse{Status: "ignored", ChannelID: channel.ID, SessionID: current.ID, StreamState: h.streamState(channel, current, live)})
+		return
+	}
+	if receiving && !h.claimPublishSource(channel, publishSourceIP(req.ClientIP), w) {
+		return
+	}
+	session, err := h.Store.RecordStreamMedia(channel.ID, receiving, h.now())
+	if errors.Is(err, storage.ErrChannelNotLive) {
+		WriteJSON(w, http.StatusOK, ingestEventResponse{Status: "ignored", ChannelID: channel.ID, StreamState: streamStateOffline})
+		return
+	}
+	if err != nil {
+		writeStorageError(w, http.StatusInternalServerError, err)
+		return
+	}
+	h.invalidateChannelCache(r.Context(), channel.ID)
+	h.logger().Info("ingest event", "event", normalizeSRSAction(req.Event), "channel_id", channel.ID, "session_id", session.ID)
+	WriteJSON(w, http.StatusOK, ingestEventResponse{Status: "ok", ChannelID: channel.ID, SessionID: session.ID, StreamState: h.streamState(channel, session, true)})
+}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+func postIngestEvent(t *testing.T, h *Handler, query, auth, body string) *httptest.ResponseRecorder {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodPost, "/api/internal/ingest/events"+query, strings.NewReader(body))
+	if auth != "" {
+		req.Header.Set("Authorization", "Bearer "+auth)
+	}
+	rec := httptest.NewRecorder()
+	h.IngestEvents(rec, req)
+	return rec
+}
+
+func decodeIngestEvent(t *testing.T, rec *httptest.ResponseRecorder) ingestEventResponse {
+	t.Helper()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp ingestEventResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode ingest event: %v", err)
+	}
+	return resp
+}
+
+func TestIngestEventsRequireToken(t *testing.T) {
+	h, store := newTestHandler(t)
+	_, channel := newStatusChannel(t, store)
+	body := `{"event":"publish","streamKey":"` + channel.StreamKey + `"}`
+
+	if rec := postIngestEvent(t, h, "", "secret", body); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 while ingest events are disabled, got %d", rec.Code)
+	}
+	h.IngestEventsToken = "secret"
+	if rec := postIngestEvent(t, h, "", "", body); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a token, got %d", rec.Code)
+	}
+	if rec := postIngestEvent(t, h, "", "wrong", body); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a wrong token, got %d", rec.Code)
+	}
+	if resp := decodeIngestEvent(t, postIngestEvent(t, h, "?token=secret", "", body)); resp.Status != "ignored" || resp.StreamState != streamStateOffline {
+		t.Fatalf("expected the query token accepted and the offline channel ignored, got %+v", resp)
+	}
+	if rec := postIngestEvent(t, h, "", "secret", `{"event":"publish","streamKey":"unknown"}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown stream key, got %d", rec.Code)
+	}
+	if rec := postIngestEvent(t, h, "", "secret", `{"event":"reboot","streamKey":"`+channel.StreamKey+`"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown event, got %d", rec.Code)
+	}
+}
+
+func TestIngestEventsDriveStreamState(t *testing.T) {
+	h, store := newTestHandler(t)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	h.Now = func() time.Time { return now }
+	_, channel := newStatusChannel(t, store)
+
+	playbackState := func() string {
+		t.Helper()
+		rec := httptest.NewRecorder()
+		h.ChannelByID(rec, httptest.NewRequest(http.MethodGet, "/api/channels/"+channel.ID+"/playback", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("playback: expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var payload channelPlaybackResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+			t.Fatalf("decode playback: %v", err)
+		}
+		status := decodeChannelStatus(t, getChannelStatus(t, h, "/api/channels/"+channel.ID+"/status", ""))
+		if status.StreamState != payload.StreamState {
+			t.Fatalf("expected status and playback to agree, got %q and %q", status.StreamState, payload.StreamState)
+		}
+		return payload.StreamState
+	}
+
+	session, err := store.StartStream(channel.ID, []string{"720p"})
+	if err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	if state := playbackState(); state != streamStateLive {
+		t.Fatalf("expected a live stream reported live without ingest events, got %q", state)
+	}
+
+	h.IngestEventsToken = "secret"
+	h.statuses.invalidate(channel.ID)
+	if state := playbackState(); state != streamStateStarting {
+		t.Fatalf("expected a stream without media to be starting, got %q", state)
+	}
+
+	steps := []struct {
+		body  string
+		state string
+	}{
+		{`{"event":"on_publish","streamKey":"` + channel.StreamKey + `"}`, streamStateLive},
+		{`{"event":"unpublish","channelId":"` + channel.ID + `","sessionId":"` + session.ID + `"}`, streamStateInterrupted},
+		{`{"event":"idle","streamKey":"` + channel.StreamKey + `"}`, streamStateInterrupted},
+	}
+	for _, step := range steps {
+		now = now.Add(time.Minute)
+		resp := decodeIngestEvent(t, postIngestEvent(t, h, "", "secret", step.body))
+		if resp.Status != "ok" || resp.SessionID != session.ID || resp.StreamState != step.state {
+			t.Fatalf("%s: expected %q for session %s, got %+v", step.body, step.state, session.ID, resp)
+		}
+		if state := playbackState(); state != step.state {
+			t.Fatalf("%s: expected playback to report %q, got %q", step.body, step.state, state)
+		}
+	}
+	current, _ := store.CurrentStreamSession(channel.ID)
+	if want := now.Add(-time.Minute); current.InterruptedAt == nil || !current.InterruptedAt.Equal(want) {
+		t.Fatalf("expected the idle event to keep the unpublish time %s, got %+v", want, current.InterruptedAt)
+	}
+
+	stale := decodeIngestEvent(t, postIngestEvent(t, h, "", "secret", `{"event":"publish","channelId":"`+channel.ID+`","sessionId":"previous"}`))
+	if stale.Status != "ignored" || stale.StreamState != streamStateInterrupted {
+		t.Fatalf("expected an event for another session ignored, got %+v", stale)
+	}
+
+	if _, err := store.StopStream(channel.ID, 0); err != nil {
+		t.Fatalf("StopStream: %v", err)
+	}
+	h.statuses.invalidate(channel.ID)
+	if state := playbackState(); state != streamStateOffline {
+		t.Fatalf("expected a stopped stream offline, got %q", state)
+	}
+}
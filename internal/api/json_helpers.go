@@ -79,14 +79,15 @@ func (e RequestError) ClientMessage() string {
 	return e.Error()
 }
 
-// WriteJSON writes a JSON payload with the provided status code.
+// WriteJSON writes a JSON payload with the provided status code. Nil slices
+// and maps in the payload are written as [] and {}.
 func WriteJSON(w http.ResponseWriter, status int, payload interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
 	if payload == nil {
 		return
 	}
-	_ = json.NewEncoder(w).Encode(payload)
+	_ = json.NewEncoder(w).Encode(emptyCollections(payload))
 }
 
 // WriteError writes a structured error payload using the provided status code.
@@ -113,7 +114,7 @@ func WriteDecodeError(w http.ResponseWriter, err error) {
 		return
 	}
 
-	WriteError(w, http.StatusBadRequest, err)
+	writeStorageError(w, http.StatusBadRequest, err)
 }
 
 // DecodeJSON parses a JSON payload into dest, rejecting unknown fields and enforcing a body size limit.
@@ -0,0 +1,175 @@
+package api
+
+import (
+	"encoding"
+	"encoding/json"
+	"reflect"
+	"sync"
+)
+
+// marshalResponse encodes payload as a response body, with nil slices and
+// maps written as [] and {} like WriteJSON does.
+func marshalResponse(payload interface{}) ([]byte, error) {
+	return json.Marshal(emptyCollections(payload))
+}
+
+// emptyCollections returns payload with every nil slice and map it reaches
+// replaced by an empty one, so declared arrays always encode as [] and
+// objects as {} rather than null. Clients can then rely on a response's
+// shape instead of checking each collection for null. Pointers that are nil
+// still encode as null, fields tagged omitempty are still omitted, and types
+// with their own JSON or text encoding are left alone. payload itself is
+// never modified; values holding collections are copied.
+func emptyCollections(payload interface{}) interface{} {
+	if payload == nil {
+		return nil
+	}
+	value := reflect.ValueOf(payload)
+	if !mayHoldCollections(value.Type()) {
+		return payload
+	}
+	return fillCollections(value).Interface()
+}
+
+var (
+	marshalerType     = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+	collectionTypes   sync.Map // reflect.Type -> bool
+)
+
+// encodesItself reports whether t controls its own JSON encoding. Byte
+// slices are included because they encode as base64 strings.
+func encodesItself(t reflect.Type) bool {
+	if t.Implements(marshalerType) || t.Implements(textMarshalerType) {
+		return true
+	}
+	if t.Kind() != reflect.Pointer {
+		ptr := reflect.PointerTo(t)
+		if ptr.Implements(marshalerType) || ptr.Implements(textMarshalerType) {
+			return true
+		}
+	}
+	return t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8
+}
+
+// mayHoldCollections reports whether values of t can contain a slice or map
+// that emptyCollections would replace. Interfaces always may.
+func mayHoldCollections(t reflect.Type) bool {
+	if cached, ok := collectionTypes.Load(t); ok {
+		return cached.(bool)
+	}
+	result := scanCollections(t, map[reflect.Type]bool{})
+	collectionTypes.Store(t, result)
+	return result
+}
+
+// scanCollections works out mayHoldCollections for t. A recursive type that
+// reaches itself is assumed to hold collections, which at worst costs a copy.
+func scanCollections(t reflect.Type, visiting map[reflect.Type]bool) bool {
+	if cached, ok := collectionTypes.Load(t); ok {
+		return cached.(bool)
+	}
+	if visiting[t] {
+		return true
+	}
+	if encodesItself(t) {
+		return false
+	}
+	visiting[t] = true
+	defer delete(visiting, t)
+	switch t.Kind() {
+	case reflect.Slice, reflect.Map, reflect.Interface:
+		return true
+	case reflect.Pointer, reflect.Array:
+		return scanCollections(t.Elem(), visiting)
+	case reflect.Struct:
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			if field.Tag.Get("json") == "-" || (!field.IsExported() && !field.Anonymous) {
+				continue
+			}
+			if scanCollections(field.Type, visiting) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func fillCollections(value reflect.Value) reflect.Value {
+	t := value.Type()
+	if !mayHoldCollections(t) {
+		return value
+	}
+	switch t.Kind() {
+	case reflect.Interface:
+		if value.IsNil() {
+			return value
+		}
+		out := reflect.New(t).Elem()
+		out.Set(fillCollections(value.Elem()))
+		return out
+	case reflect.Pointer:
+		if value.IsNil() {
+			return value
+		}
+		out := reflect.New(t.Elem())
+		out.Elem().Set(fillCollections(value.Elem()))
+		return out
+	case reflect.Slice:
+		if value.IsNil() {
+			return reflect.MakeSlice(t, 0, 0)
+		}
+		if !mayHoldCollections(t.Elem()) {
+			return value
+		}
+		out := reflect.MakeSlice(t, value.Len(), value.Len())
+		for i := 0; i < value.Len(); i++ {
+			out.Index(i).Set(fillCollections(value.Index(i)))
+		}
+		return out
+	case reflect.Array:
+		out := reflect.New(t).Elem()
+		for i := 0; i < value.Len(); i++ {
+			out.Index(i).Set(fillCollections(value.Index(i)))
+		}
+		return out
+	case reflect.Map:
+		if value.IsNil() {
+			return reflect.MakeMap(t)
+		}
+		if !mayHoldCollections(t.Elem()) {
+			return value
+		}
+		out := reflect.MakeMapWithSize(t, value.Len())
+		iter := value.MapRange()
+		for iter.Next() {
+			out.SetMapIndex(iter.Key(), fillCollections(iter.Value()))
+		}
+		return out
+	case reflect.Struct:
+		out := reflect.New(t).Elem()
+		out.Set(value)
+		fillStructFields(out)
+		return out
+	}
+	return value
+}
+
+// fillStructFields fills the collections of the addressable struct out in
+// place, including the promoted fields of embedded unexported structs.
+func fillStructFields(out reflect.Value) {
+	t := out.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field := t.Field(i)
+		if field.Tag.Get("json") == "-" || !mayHoldCollections(field.Type) {
+			continue
+		}
+		switch {
+		case field.IsExported():
+			out.Field(i).Set(fillCollections(out.Field(i)))
+		case field.Anonymous && field.Type.Kind() == reflect.Struct:
+			fillStructFields(out.Field(i))
+		}
+	}
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bitriver-live/internal/ingest"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+const (
+	defaultLiveClipLimit  = 3
+	defaultLiveClipWindow = time.Minute
+)
+
+type liveClipRequest struct {
+	Title           string `json:"title"`
+	DurationSeconds int    `json:"durationSeconds"`
+}
+
+type limiterWindow struct {
+	started time.Time
+	count   int
+}
+
+// windowLimiter counts attempts per key in fixed windows, such as clips per
+// viewer and channel so a viewer cannot keep the transcoder busy cutting
+// clips. The zero value is ready to use.
+type windowLimiter struct {
+	mu      sync.Mutex
+	windows map[string]limiterWindow
+}
+
+// allow records an attempt for key and reports whether it fits in limit per
+// window. When it does not, the returned duration is how long until the
+// window resets.
+func (l *windowLimiter) allow(key string, limit int, window time.Duration, now time.Time) (bool, time.Duration) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.windows == nil {
+		l.windows = make(map[string]limiterWindow)
+	}
+	for existing, entry := range l.windows {
+		if now.Sub(entry.started) >= window {
+			delete(l.windows, existing)
+		}
+	}
+	entry := l.windows[key]
+	if entry.count == 0 {
+		entry.started = now
+	}
+	if entry.count >= limit {
+		return false, entry.started.Add(window).Sub(now)
+	}
+	entry.count++
+	l.windows[key] = entry
+	return true, 0
+}
+
+// liveClipRate returns the configured clips-per-window limit, falling back
+// to the defaults for unset fields.
+func (h *Handler) liveClipRate() (int, time.Duration) {
+	limit := h.LiveClipLimit
+	if limit <= 0 {
+		limit = defaultLiveClipLimit
+	}
+	window := h.LiveClipWindow
+	if window <= 0 {
+		window = defaultLiveClipWindow
+	}
+	return limit, window
+}
+
+// channelOfflineError answers requests that need the channel to be live.
+func channelOfflineError(channel models.Channel, err error) RequestError {
+	return RequestError{Status: http.StatusConflict, CodeVal: "channel_offline", Message: fmt.Sprintf("channel %s is not live", channel.ID), Err: err}
+}
+
+// handleLiveClip lets any signed-in viewer clip the last seconds of a live
+// channel. The clip is stored against the live session and linked to the
+// recording once the stream ends.
+func (h *Handler) handleLiveClip(channel models.Channel, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+	viewer, ok := h.requireAuthenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	var req liveClipRequest
+	if r.ContentLength != 0 && !DecodeAndValidate(w, r, &req) {
+		return
+	}
+	if req.DurationSeconds < 0 || req.DurationSeconds > storage.MaxLiveClipSeconds {
+		WriteRequestError(w, ValidationError(fmt.Sprintf("durationSeconds must be between 1 and %d", storage.MaxLiveClipSeconds)))
+		return
+	}
+	now := h.now()
+	if reason := ageGate(channel, &viewer, now); reason != "" {
+		WriteRequestError(w, ageGateError(reason))
+		return
+	}
+	if channel.LiveState != "live" {
+		WriteRequestError(w, channelOfflineError(channel, nil))
+		return
+	}
+
+	limit, window := h.liveClipRate()
+	if allowed, retry := h.liveClips.allow(viewer.ID+"|"+channel.ID, limit, window, now); !allowed {
+		seconds := int((retry + time.Second - 1) / time.Second)
+		if seconds < 1 {
+			seconds = 1
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		WriteRequestError(w, RequestError{Status: http.StatusTooManyRequests, CodeVal: "rate_limited", Message: fmt.Sprintf("you can create %d clips per channel every %s", limit, window)})
+		return
+	}
+
+	title := strings.TrimSpace(req.Title)
+	if title == "" {
+		title = channel.Title
+	}
+	clip, err := h.Store.CreateLiveClip(channel.ID, storage.LiveClipParams{
+		UserID:          viewer.ID,
+		Title:           title,
+		DurationSeconds: req.DurationSeconds,
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrChannelNotLive):
+			WriteRequestError(w, channelOfflineError(channel, err))
+		case errors.Is(err, ingest.ErrClipUnavailable):
+			WriteRequestError(w, RequestError{Status: http.StatusConflict, CodeVal: "clip_unavailable", Message: "no video has been buffered to clip yet", Err: err})
+		case errors.Is(err, storage.ErrIngestControllerUnavailable):
+			WriteRequestError(w, ServiceUnavailableError("live clips are unavailable"))
+		default:
+			WriteError(w, http.StatusBadGateway, fmt.Errorf("create clip: %w", err))
+		}
+		return
+	}
+	WriteJSON(w, http.StatusCreated, newClipExportResponse(clip))
+}
@@ -0,0 +1,118 @@
+package api
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"sort"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/authz"
+	"bitriver-live/internal/observability/logging"
+)
+
+// maxLogLevelRevert caps how long a runtime log level change may last
+// before it reverts on its own.
+const maxLogLevelRevert = 24 * time.Hour
+
+type logLevelsRequest struct {
+	// Level is the new global level. Empty keeps the current one.
+	Level string `json:"level"`
+	// Components replaces every component override; omitted components
+	// follow the global level again.
+	Components map[string]string `json:"components"`
+	// RevertAfter is a Go duration after which the startup levels return.
+	RevertAfter string `json:"revertAfter"`
+}
+
+type logLevelsResponse struct {
+	Level        string            `json:"level"`
+	StartupLevel string            `json:"startupLevel"`
+	Components   map[string]string `json:"components"`
+	RevertAt     *string           `json:"revertAt,omitempty"`
+}
+
+func newLogLevelsResponse(levels *logging.LevelController, state logging.LevelState) logLevelsResponse {
+	response := logLevelsResponse{
+		Level:        logging.LevelName(state.Level),
+		StartupLevel: logging.LevelName(levels.StartupLevel()),
+		Components:   make(map[string]string, len(state.Components)),
+	}
+	for component, level := range state.Components {
+		response.Components[component] = logging.LevelName(level)
+	}
+	if !state.RevertAt.IsZero() {
+		revertAt := state.RevertAt.UTC().Format(time.RFC3339)
+		response.RevertAt = &revertAt
+	}
+	return response
+}
+
+// AdminLogging reads and changes this process's log levels at runtime.
+func (h *Handler) AdminLogging(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPut {
+		WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodPut)
+		return
+	}
+	actor, ok := h.requirePermission(w, r, authz.PlatformManage)
+	if !ok {
+		return
+	}
+	levels := h.LogLevels
+	if levels == nil {
+		WriteRequestError(w, RequestError{Status: http.StatusServiceUnavailable, CodeVal: "service_unavailable", Message: "runtime log levels are not configured"})
+		return
+	}
+	if r.Method == http.MethodGet {
+		WriteJSON(w, http.StatusOK, newLogLevelsResponse(levels, levels.State()))
+		return
+	}
+
+	var req logLevelsRequest
+	if !DecodeAndValidate(w, r, &req) {
+		return
+	}
+	level := levels.State().Level
+	if strings.TrimSpace(req.Level) != "" {
+		parsed, err := logging.ParseLevel(req.Level)
+		if err != nil {
+			WriteRequestError(w, ValidationError("level must be debug, info, warn, or error"))
+			return
+		}
+		level = parsed
+	}
+	components := make(map[string]slog.Level, len(req.Components))
+	for component, name := range req.Components {
+		component = strings.TrimSpace(component)
+		if component == "" {
+			WriteRequestError(w, ValidationError("component names must not be empty"))
+			return
+		}
+		parsed, err := logging.ParseLevel(name)
+		if err != nil {
+			WriteRequestError(w, ValidationError(fmt.Sprintf("level for component %s must be debug, info, warn, or error", component)))
+			return
+		}
+		components[component] = parsed
+	}
+	var revertAfter time.Duration
+	if strings.TrimSpace(req.RevertAfter) != "" {
+		parsed, err := time.ParseDuration(strings.TrimSpace(req.RevertAfter))
+		if err != nil || parsed <= 0 || parsed > maxLogLevelRevert {
+			WriteRequestError(w, ValidationError(fmt.Sprintf("revertAfter must be a positive duration of at most %s", maxLogLevelRevert)))
+			return
+		}
+		revertAfter = parsed
+	}
+
+	state := levels.Set(level, components, revertAfter)
+	response := newLogLevelsResponse(levels, state)
+	overrides := make([]string, 0, len(response.Components))
+	for component, name := range response.Components {
+		overrides = append(overrides, component+"="+name)
+	}
+	sort.Strings(overrides)
+	h.auditLogger().Info("audit", "action", "logging.update", "user_id", actor.ID, "level", response.Level, "components", strings.Join(overrides, ","), "revert_after", revertAfter.String())
+	WriteJSON(w, http.StatusOK, response)
+}
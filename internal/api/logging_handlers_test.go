@@ -0,0 +1,64 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"bitriver-live/internal/observability/logging"
+	"bitriver-live/internal/storage"
+)
+
+func TestAdminLoggingChangesLevels(t *testing.T) {
+	handler, store := newTestHandler(t)
+	admin, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Admin", Email: "admin@example.com", Roles: []string{"admin"}})
+	if err != nil {
+		t.Fatalf("CreateUser admin: %v", err)
+	}
+	var audit bytes.Buffer
+	handler.AuditLogger = slog.New(slog.NewJSONHandler(&audit, nil))
+	serve := func(method, body string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(method, "/api/admin/logging", strings.NewReader(body)), admin)
+		rec := httptest.NewRecorder()
+		handler.AdminLogging(rec, req)
+		return rec
+	}
+
+	if rec := serve(http.MethodGet, ""); rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without a level controller, got %d", rec.Code)
+	}
+	handler.LogLevels = logging.NewLevelController("info")
+
+	rec := serve(http.MethodPut, `{"level":"warn","components":{"ingest":"debug"},"revertAfter":"30m"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = serve(http.MethodGet, "")
+	var state logLevelsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &state); err != nil {
+		t.Fatalf("decode levels: %v", err)
+	}
+	if state.Level != "warn" || state.StartupLevel != "info" || state.Components["ingest"] != "debug" || state.RevertAt == nil {
+		t.Fatalf("unexpected levels %+v", state)
+	}
+	var event map[string]any
+	if err := json.Unmarshal(audit.Bytes(), &event); err != nil {
+		t.Fatalf("decode audit event: %v (%s)", err, audit.String())
+	}
+	if event["action"] != "logging.update" || event["user_id"] != admin.ID || event["components"] != "ingest=debug" || event["revert_after"] != "30m0s" {
+		t.Fatalf("unexpected audit event %v", event)
+	}
+
+	for _, body := range []string{`{"level":"verbose"}`, `{"components":{"ingest":"loud"}}`, `{"revertAfter":"48h"}`, `{"revertAfter":"-1m"}`} {
+		if rec := serve(http.MethodPut, body); rec.Code != http.StatusBadRequest {
+			t.Fatalf("expected 400 for %s, got %d", body, rec.Code)
+		}
+	}
+	if got := handler.LogLevels.State().Level; got != slog.LevelWarn {
+		t.Fatalf("expected rejected updates to leave the levels alone, got %s", got)
+	}
+}
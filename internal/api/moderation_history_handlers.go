@@ -0,0 +1,65 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"time"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+type moderationLogEntryResponse struct {
+	ID        string            `json:"id"`
+	Action    string            `json:"action"`
+	ActorID   string            `json:"actorId,omitempty"`
+	Reason    string            `json:"reason,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt string            `json:"createdAt"`
+}
+
+// handleModerationHistory serves GET
+// /api/channels/{id}/users/{userID}/moderation-history: every ban, timeout,
+// deleted message, and resolved report against the user in the channel,
+// newest first and paged by page and perPage. Only the channel's moderators
+// may read it.
+func (h *Handler) handleModerationHistory(channel models.Channel, userID string, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	actor, ok := h.requireAuthenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	if !h.canModerateChannel(actor, channel) {
+		WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
+		return
+	}
+	if _, exists := h.Store.GetUser(userID); !exists {
+		WriteError(w, http.StatusNotFound, fmt.Errorf("user %s not found", userID))
+		return
+	}
+	page, perPage, err := parsePageParams(r.URL.Query())
+	if err != nil {
+		WriteRequestError(w, err)
+		return
+	}
+	history, err := h.Store.ListModerationHistory(channel.ID, userID, storage.ModerationHistoryOptions{Limit: perPage, Offset: (page - 1) * perPage})
+	if err != nil {
+		writeStorageError(w, http.StatusInternalServerError, err)
+		return
+	}
+	items := make([]moderationLogEntryResponse, 0, len(history.Actions))
+	for _, action := range history.Actions {
+		items = append(items, moderationLogEntryResponse{
+			ID:        action.ID,
+			Action:    action.Action,
+			ActorID:   action.ActorID,
+			Reason:    action.Reason,
+			Metadata:  action.Metadata,
+			CreatedAt: action.CreatedAt.Format(time.RFC3339Nano),
+		})
+	}
+	WriteJSON(w, http.StatusOK, pageResponse{Items: items, Total: history.Total, Page: page, PerPage: perPage})
+}
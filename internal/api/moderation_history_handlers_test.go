@@ -0,0 +1,108 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/chat"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+func TestModerationHistoryPaginatesNewestFirst(t *testing.T) {
+	handler, store := newTestHandler(t)
+	users := make(map[string]models.User)
+	for name, roles := range map[string][]string{"creator": {"creator"}, "moderator": {"moderator"}, "admin": {"admin"}, "viewer": nil, "target": nil} {
+		user, err := store.CreateUser(storage.CreateUserParams{DisplayName: name, Email: name + "@example.com", Roles: roles})
+		if err != nil {
+			t.Fatalf("CreateUser %s: %v", name, err)
+		}
+		users[name] = user
+	}
+	channel, err := store.CreateChannel(users["creator"].ID, "Studio", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+
+	start := time.Now().UTC().Add(-time.Hour)
+	for i, action := range []chat.ModerationAction{chat.ModerationActionBan, chat.ModerationActionUnban} {
+		if err := store.ApplyChatEvent(chat.Event{
+			Type:       chat.EventTypeModeration,
+			Moderation: &chat.ModerationEvent{Action: action, ChannelID: channel.ID, ActorID: users["creator"].ID, TargetID: users["target"].ID, Reason: "spam"},
+			OccurredAt: start.Add(time.Duration(i) * time.Minute),
+		}); err != nil {
+			t.Fatalf("ApplyChatEvent %s: %v", action, err)
+		}
+	}
+	message, err := store.CreateChatMessage(channel.ID, users["target"].ID, "spam spam", "")
+	if err != nil {
+		t.Fatalf("CreateChatMessage: %v", err)
+	}
+	if err := store.DeleteChatMessage(channel.ID, message.ID, users["moderator"].ID); err != nil {
+		t.Fatalf("DeleteChatMessage: %v", err)
+	}
+
+	history := func(user *models.User, query string) (*httptest.ResponseRecorder, []moderationLogEntryResponse, pageResponse) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/channels/"+channel.ID+"/users/"+users["target"].ID+"/moderation-history"+query, nil)
+		if user != nil {
+			req = withUser(req, *user)
+		}
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		var items []moderationLogEntryResponse
+		page := pageResponse{Items: &items}
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+				t.Fatalf("decode response: %v", err)
+			}
+		}
+		return rec, items, page
+	}
+
+	creator := users["creator"]
+	rec, items, page := history(&creator, "?perPage=2")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if page.Total != 3 || page.Page != 1 || page.PerPage != 2 || len(items) != 2 {
+		t.Fatalf("unexpected first page %+v with %d items", page, len(items))
+	}
+	if items[0].Action != models.ModerationActionDeleteMessage || items[0].ActorID != users["moderator"].ID || items[0].Metadata["content"] != "spam spam" {
+		t.Fatalf("expected the deleted message first, got %+v", items[0])
+	}
+	if items[1].Action != models.ModerationActionUnban {
+		t.Fatalf("expected the unban second, got %+v", items[1])
+	}
+	_, items, _ = history(&creator, "?page=2&perPage=2")
+	if len(items) != 1 || items[0].Action != models.ModerationActionBan || items[0].Reason != "spam" {
+		t.Fatalf("unexpected second page %+v", items)
+	}
+
+	for _, name := range []string{"moderator", "admin"} {
+		user := users[name]
+		if rec, _, _ := history(&user, ""); rec.Code != http.StatusOK {
+			t.Fatalf("expected %s to read the history, got %d", name, rec.Code)
+		}
+	}
+	viewer := users["viewer"]
+	if rec, _, _ := history(&viewer, ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a viewer, got %d", rec.Code)
+	}
+	if rec, _, _ := history(nil, ""); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a session, got %d", rec.Code)
+	}
+	if rec, _, _ := history(&creator, "?perPage=0"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for invalid perPage, got %d", rec.Code)
+	}
+
+	req := withUser(httptest.NewRequest(http.MethodGet, "/api/channels/"+channel.ID+"/users/missing/moderation-history", nil), creator)
+	rec = httptest.NewRecorder()
+	handler.ChannelByID(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown user, got %d", rec.Code)
+	}
+}
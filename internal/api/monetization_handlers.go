@@ -4,10 +4,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"net/http"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
 
+	"bitriver-live/internal/authz"
+	"bitriver-live/internal/chat"
 	"bitriver-live/internal/models"
 	"bitriver-live/internal/observability/metrics"
 	"bitriver-live/internal/storage"
@@ -46,6 +49,32 @@ type createSubscriptionRequest struct {
 	AutoRenew         bool        `json:"autoRenew"`
 }
 
+type giftSubscriptionsRequest struct {
+	Count        int         `json:"count"`
+	RecipientIDs []string    `json:"recipientIds,omitempty"`
+	Tier         string      `json:"tier"`
+	Provider     string      `json:"provider"`
+	Reference    string      `json:"reference,omitempty"`
+	Amount       json.Number `json:"amount"`
+	Currency     string      `json:"currency"`
+	DurationDays int         `json:"durationDays"`
+}
+
+type giftSubscriptionsResponse struct {
+	ChannelID     string                 `json:"channelId"`
+	GifterID      string                 `json:"gifterId"`
+	Count         int                    `json:"count"`
+	Subscriptions []subscriptionResponse `json:"subscriptions"`
+}
+
+// recentChatterWindow bounds how far back gift recipients are drawn from when
+// the gifter does not name them.
+const recentChatterWindow = time.Hour
+
+// recentChatterScanLimit caps how many chat messages are inspected when
+// collecting recent chatters.
+const recentChatterScanLimit = 1000
+
 type subscriptionResponse struct {
 	ID                string       `json:"id"`
 	ChannelID         string       `json:"channelId"`
@@ -63,6 +92,8 @@ type subscriptionResponse struct {
 	CancelledBy       string       `json:"cancelledBy,omitempty"`
 	CancelledReason   string       `json:"cancelledReason,omitempty"`
 	CancelledAt       *string      `json:"cancelledAt,omitempty"`
+	GifterID          string       `json:"gifterId,omitempty"`
+	IsGift            bool         `json:"isGift,omitempty"`
 }
 
 func parseMoneyNumber(number json.Number, field string) (models.Money, error) {
@@ -109,6 +140,8 @@ func newSubscriptionResponse(sub models.Subscription) subscriptionResponse {
 		Status:            sub.Status,
 		CancelledBy:       sub.CancelledBy,
 		CancelledReason:   sub.CancelledReason,
+		GifterID:          sub.GifterID,
+		IsGift:            sub.IsGift,
 	}
 	if sub.CancelledAt != nil {
 		cancelled := sub.CancelledAt.Format(time.RFC3339Nano)
@@ -117,6 +150,56 @@ func newSubscriptionResponse(sub models.Subscription) subscriptionResponse {
 	return resp
 }
 
+// subscriptionStandingResponse is the viewer's own subscription standing
+// with a channel. Months counts every month subscribed, across gaps, and
+// SubscriberTier is the chat badge tier those months earn while subscribed.
+type subscriptionStandingResponse struct {
+	ChannelID      string  `json:"channelId"`
+	Subscribed     bool    `json:"subscribed"`
+	Months         int     `json:"months"`
+	SubscriberTier int     `json:"subscriberTier,omitempty"`
+	ExpiresAt      *string `json:"expiresAt,omitempty"`
+}
+
+func newSubscriptionStandingResponse(standing storage.SubscriptionStanding) subscriptionStandingResponse {
+	resp := subscriptionStandingResponse{
+		ChannelID:  standing.ChannelID,
+		Subscribed: standing.Active,
+		Months:     standing.Months,
+	}
+	if standing.Active {
+		resp.SubscriberTier = chat.SubscriberTier(standing.Months)
+	}
+	if standing.ExpiresAt != nil {
+		expires := standing.ExpiresAt.Format(time.RFC3339Nano)
+		resp.ExpiresAt = &expires
+	}
+	return resp
+}
+
+// handleChannelSubscription serves GET /api/channels/{id}/subscription, the
+// signed-in viewer's subscription standing with the channel.
+func (h *Handler) handleChannelSubscription(channel models.Channel, remaining []string, w http.ResponseWriter, r *http.Request) {
+	if len(remaining) > 0 && remaining[0] != "" {
+		WriteError(w, http.StatusNotFound, fmt.Errorf("unknown subscription path"))
+		return
+	}
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	actor, ok := h.requireAuthenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	standing, err := h.Store.SubscriptionStanding(channel.ID, actor.ID)
+	if err != nil {
+		writeStorageError(w, http.StatusInternalServerError, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, newSubscriptionStandingResponse(standing))
+}
+
 func (h *Handler) handleMonetizationRoutes(channel models.Channel, remaining []string, w http.ResponseWriter, r *http.Request) {
 	if len(remaining) == 0 {
 		WriteError(w, http.StatusNotFound, fmt.Errorf("unknown monetization path"))
@@ -143,7 +226,7 @@ func (h *Handler) handleTipsRoutes(channel models.Channel, remaining []string, w
 	}
 	switch r.Method {
 	case http.MethodGet:
-		if channel.OwnerID != actor.ID && !actor.HasRole(roleAdmin) {
+		if !canViewChannelMonetization(actor, channel) {
 			WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
 			return
 		}
@@ -155,7 +238,7 @@ func (h *Handler) handleTipsRoutes(channel models.Channel, remaining []string, w
 		}
 		tips, err := h.Store.ListTips(channel.ID, limit)
 		if err != nil {
-			WriteError(w, http.StatusBadRequest, err)
+			writeStorageError(w, http.StatusBadRequest, err)
 			return
 		}
 		response := make([]tipResponse, 0, len(tips))
@@ -170,7 +253,7 @@ func (h *Handler) handleTipsRoutes(channel models.Channel, remaining []string, w
 		}
 		amount, err := parseMoneyNumber(req.Amount, "amount")
 		if err != nil {
-			WriteError(w, http.StatusBadRequest, err)
+			writeStorageError(w, http.StatusBadRequest, err)
 			return
 		}
 		params := storage.CreateTipParams{
@@ -185,7 +268,7 @@ func (h *Handler) handleTipsRoutes(channel models.Channel, remaining []string, w
 		}
 		tip, err := h.Store.CreateTip(params)
 		if err != nil {
-			WriteError(w, http.StatusBadRequest, err)
+			writeStorageError(w, http.StatusBadRequest, err)
 			return
 		}
 		metrics.Default().ObserveMonetization("tip", tip.Amount)
@@ -200,6 +283,10 @@ func (h *Handler) handleSubscriptionsRoutes(channel models.Channel, remaining []
 	if !ok {
 		return
 	}
+	if len(remaining) == 1 && remaining[0] == "gift" {
+		h.handleGiftSubscriptions(channel, actor, w, r)
+		return
+	}
 	if len(remaining) > 0 && strings.TrimSpace(remaining[0]) != "" {
 		subscriptionID := remaining[0]
 		if len(remaining) == 1 {
@@ -212,16 +299,17 @@ func (h *Handler) handleSubscriptionsRoutes(channel models.Channel, remaining []
 				WriteError(w, http.StatusNotFound, fmt.Errorf("subscription %s not found", subscriptionID))
 				return
 			}
-			if sub.UserID != actor.ID && channel.OwnerID != actor.ID && !actor.HasRole(roleAdmin) {
+			if sub.UserID != actor.ID && channel.OwnerID != actor.ID && !authz.Has(actor, authz.ChannelsManageAny) {
 				WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
 				return
 			}
 			reason := strings.TrimSpace(r.URL.Query().Get("reason"))
 			updated, err := h.Store.CancelSubscription(subscriptionID, actor.ID, reason)
 			if err != nil {
-				WriteError(w, http.StatusBadRequest, err)
+				writeStorageError(w, http.StatusBadRequest, err)
 				return
 			}
+			h.invalidateChatAuthor(updated.UserID)
 			WriteJSON(w, http.StatusOK, newSubscriptionResponse(updated))
 			return
 		}
@@ -231,7 +319,7 @@ func (h *Handler) handleSubscriptionsRoutes(channel models.Channel, remaining []
 
 	switch r.Method {
 	case http.MethodGet:
-		if channel.OwnerID != actor.ID && !actor.HasRole(roleAdmin) {
+		if !canViewChannelMonetization(actor, channel) {
 			WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
 			return
 		}
@@ -242,7 +330,7 @@ func (h *Handler) handleSubscriptionsRoutes(channel models.Channel, remaining []
 		}
 		subs, err := h.Store.ListSubscriptions(channel.ID, includeInactive)
 		if err != nil {
-			WriteError(w, http.StatusBadRequest, err)
+			writeStorageError(w, http.StatusBadRequest, err)
 			return
 		}
 		response := make([]subscriptionResponse, 0, len(subs))
@@ -262,7 +350,7 @@ func (h *Handler) handleSubscriptionsRoutes(channel models.Channel, remaining []
 		}
 		amount, err := parseMoneyNumber(req.Amount, "amount")
 		if err != nil {
-			WriteError(w, http.StatusBadRequest, err)
+			writeStorageError(w, http.StatusBadRequest, err)
 			return
 		}
 		params := storage.CreateSubscriptionParams{
@@ -279,12 +367,144 @@ func (h *Handler) handleSubscriptionsRoutes(channel models.Channel, remaining []
 		}
 		sub, err := h.Store.CreateSubscription(params)
 		if err != nil {
-			WriteError(w, http.StatusBadRequest, err)
+			writeStorageError(w, http.StatusBadRequest, err)
 			return
 		}
 		metrics.Default().ObserveMonetization("subscription", sub.Amount)
+		h.invalidateChatAuthor(sub.UserID)
 		WriteJSON(w, http.StatusCreated, newSubscriptionResponse(sub))
 	default:
 		WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodPost, http.MethodDelete)
 	}
 }
+
+// handleGiftSubscriptions serves POST /api/channels/{id}/subscriptions/gift.
+// Recipients default to a random selection of viewers who chatted in the
+// channel within the last hour.
+func (h *Handler) handleGiftSubscriptions(channel models.Channel, actor models.User, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+	var req giftSubscriptionsRequest
+	if !DecodeAndValidate(w, r, &req) {
+		return
+	}
+	if req.DurationDays <= 0 {
+		WriteRequestError(w, ValidationError("durationDays must be positive"))
+		return
+	}
+	if req.Count < 0 || req.Count > storage.MaxGiftSubscriptionCount {
+		WriteRequestError(w, ValidationError(fmt.Sprintf("count must be between 1 and %d", storage.MaxGiftSubscriptionCount)))
+		return
+	}
+	amount, err := parseMoneyNumber(req.Amount, "amount")
+	if err != nil {
+		writeStorageError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	recipients := req.RecipientIDs
+	if len(recipients) > 0 {
+		if req.Count != 0 && req.Count != len(recipients) {
+			WriteRequestError(w, ValidationError("count must match the number of recipientIds"))
+			return
+		}
+	} else {
+		if req.Count == 0 {
+			WriteRequestError(w, ValidationError("count or recipientIds is required"))
+			return
+		}
+		candidates, err := h.recentChatters(channel.ID, actor.ID)
+		if err != nil {
+			writeStorageError(w, http.StatusBadRequest, err)
+			return
+		}
+		if len(candidates) < req.Count {
+			WriteRequestError(w, ValidationError(fmt.Sprintf("only %d recent chatters are eligible for gifts", len(candidates))))
+			return
+		}
+		recipients = h.pickRandom(candidates, req.Count)
+	}
+
+	subs, err := h.Store.GiftSubscriptions(storage.GiftSubscriptionsParams{
+		ChannelID:    channel.ID,
+		GifterID:     actor.ID,
+		RecipientIDs: recipients,
+		Tier:         req.Tier,
+		Provider:     req.Provider,
+		Reference:    req.Reference,
+		Amount:       amount,
+		Currency:     req.Currency,
+		Duration:     time.Duration(req.DurationDays) * 24 * time.Hour,
+	})
+	if err != nil {
+		writeStorageError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	response := giftSubscriptionsResponse{
+		ChannelID:     channel.ID,
+		GifterID:      actor.ID,
+		Count:         len(subs),
+		Subscriptions: make([]subscriptionResponse, 0, len(subs)),
+	}
+	recipientIDs := make([]string, 0, len(subs))
+	for _, sub := range subs {
+		metrics.Default().ObserveMonetization("subscription", amount)
+		response.Subscriptions = append(response.Subscriptions, newSubscriptionResponse(sub))
+		recipientIDs = append(recipientIDs, sub.UserID)
+		h.invalidateChatAuthor(sub.UserID)
+	}
+	if h.ChatGateway != nil && len(subs) > 0 {
+		gift := chat.GiftEvent{
+			ChannelID:    channel.ID,
+			GifterID:     actor.ID,
+			RecipientIDs: recipientIDs,
+			Tier:         subs[0].Tier,
+			Count:        len(subs),
+		}
+		if err := h.ChatGateway.AnnounceGift(r.Context(), gift); err != nil {
+			h.logger().Warn("failed to announce subscription gift", "channel_id", channel.ID, "error", err)
+		}
+	}
+	WriteJSON(w, http.StatusCreated, response)
+}
+
+// recentChatters returns the sorted IDs of viewers, other than the gifter,
+// who sent a message in the channel within recentChatterWindow.
+func (h *Handler) recentChatters(channelID, gifterID string) ([]string, error) {
+	messages, err := h.Store.ListChatMessages(channelID, recentChatterScanLimit)
+	if err != nil {
+		return nil, err
+	}
+	cutoff := time.Now().UTC().Add(-recentChatterWindow)
+	seen := make(map[string]struct{})
+	chatters := make([]string, 0)
+	for _, message := range messages {
+		if message.CreatedAt.Before(cutoff) {
+			break
+		}
+		if message.UserID == gifterID {
+			continue
+		}
+		if _, ok := seen[message.UserID]; ok {
+			continue
+		}
+		seen[message.UserID] = struct{}{}
+		chatters = append(chatters, message.UserID)
+	}
+	sort.Strings(chatters)
+	return chatters, nil
+}
+
+// pickRandom selects n distinct entries from candidates using a partial
+// Fisher-Yates shuffle driven by the handler's random source.
+func (h *Handler) pickRandom(candidates []string, n int) []string {
+	pool := append([]string(nil), candidates...)
+	for i := 0; i < n; i++ {
+		j := i + h.randomIntn(len(pool)-i)
+		pool[i], pool[j] = pool[j], pool[i]
+	}
+	return pool[:n]
+}
@@ -0,0 +1,94 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+type notificationDeliveryResponse struct {
+	InApp bool `json:"inApp"`
+	Email bool `json:"email"`
+}
+
+type notificationPreferencesResponse struct {
+	FollowedLive notificationDeliveryResponse `json:"followedLive"`
+	Mentions     notificationDeliveryResponse `json:"mentions"`
+	Moderation   notificationDeliveryResponse `json:"moderation"`
+	Account      notificationDeliveryResponse `json:"account"`
+	UpdatedAt    *string                      `json:"updatedAt,omitempty"`
+}
+
+func newNotificationPreferencesResponse(prefs models.NotificationPreferences) notificationPreferencesResponse {
+	response := notificationPreferencesResponse{
+		FollowedLive: notificationDeliveryResponse(prefs.FollowedLive),
+		Mentions:     notificationDeliveryResponse(prefs.Mentions),
+		Moderation:   notificationDeliveryResponse(prefs.Moderation),
+		Account:      notificationDeliveryResponse(prefs.Account),
+	}
+	if prefs.UpdatedAt != nil {
+		updated := prefs.UpdatedAt.Format(time.RFC3339Nano)
+		response.UpdatedAt = &updated
+	}
+	return response
+}
+
+type notificationDeliveryRequest struct {
+	InApp *bool `json:"inApp"`
+	Email *bool `json:"email"`
+}
+
+func (r *notificationDeliveryRequest) update() *storage.NotificationDeliveryUpdate {
+	if r == nil {
+		return nil
+	}
+	return &storage.NotificationDeliveryUpdate{InApp: r.InApp, Email: r.Email}
+}
+
+// updateNotificationPreferencesRequest only names the known categories, so
+// decoding rejects unknown ones as unknown fields.
+type updateNotificationPreferencesRequest struct {
+	FollowedLive *notificationDeliveryRequest `json:"followedLive"`
+	Mentions     *notificationDeliveryRequest `json:"mentions"`
+	Moderation   *notificationDeliveryRequest `json:"moderation"`
+	Account      *notificationDeliveryRequest `json:"account"`
+}
+
+// notificationPreferences serves /api/users/me/notification-preferences. GET
+// returns the signed-in user's preferences, defaults included, and PATCH
+// changes only the categories and delivery methods present in the body.
+func (h *Handler) notificationPreferences(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.requireAuthenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		prefs, err := h.Store.GetNotificationPreferences(user.ID)
+		if err != nil {
+			writeStorageError(w, http.StatusInternalServerError, err)
+			return
+		}
+		WriteJSON(w, http.StatusOK, newNotificationPreferencesResponse(prefs))
+	case http.MethodPatch:
+		var req updateNotificationPreferencesRequest
+		if !DecodeAndValidate(w, r, &req) {
+			return
+		}
+		prefs, err := h.Store.UpdateNotificationPreferences(user.ID, storage.NotificationPreferencesUpdate{
+			FollowedLive: req.FollowedLive.update(),
+			Mentions:     req.Mentions.update(),
+			Moderation:   req.Moderation.update(),
+			Account:      req.Account.update(),
+		})
+		if err != nil {
+			writeStorageError(w, http.StatusInternalServerError, err)
+			return
+		}
+		WriteJSON(w, http.StatusOK, newNotificationPreferencesResponse(prefs))
+	default:
+		WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodPatch)
+	}
+}
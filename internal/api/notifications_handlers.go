@@ -0,0 +1,70 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/models"
+)
+
+const (
+	defaultNotificationsLimit = 50
+	maxNotificationsLimit     = 200
+)
+
+type notificationResponse struct {
+	ID        string            `json:"id"`
+	Category  string            `json:"category"`
+	Kind      string            `json:"kind"`
+	ChannelID string            `json:"channelId,omitempty"`
+	Message   string            `json:"message"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt string            `json:"createdAt"`
+}
+
+func newNotificationResponse(notification models.Notification) notificationResponse {
+	return notificationResponse{
+		ID:        notification.ID,
+		Category:  notification.Category,
+		Kind:      notification.Kind,
+		ChannelID: notification.ChannelID,
+		Message:   notification.Message,
+		Metadata:  notification.Metadata,
+		CreatedAt: notification.CreatedAt.Format(time.RFC3339Nano),
+	}
+}
+
+// userNotifications serves GET /api/users/me/notifications, the signed-in
+// user's newest in-app notifications. limit defaults to 50 and is capped at
+// 200.
+func (h *Handler) userNotifications(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.requireAuthenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	limit := defaultNotificationsLimit
+	if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			WriteRequestError(w, ValidationError("limit must be a positive integer"))
+			return
+		}
+		limit = min(parsed, maxNotificationsLimit)
+	}
+	notifications, err := h.Store.ListNotifications(user.ID, limit)
+	if err != nil {
+		writeStorageError(w, http.StatusInternalServerError, err)
+		return
+	}
+	response := make([]notificationResponse, 0, len(notifications))
+	for _, notification := range notifications {
+		response = append(response, newNotificationResponse(notification))
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,86 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"bitriver-live/internal/auth"
+	"bitriver-live/internal/mail"
+	"bitriver-live/internal/models"
+)
+
+type changePasswordRequest struct {
+	CurrentPassword string `json:"currentPassword"`
+	NewPassword     string `json:"newPassword"`
+}
+
+// changePassword replaces the signed-in user's password after checking the
+// current one, then emails them a security notice.
+func (h *Handler) changePassword(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+	user, ok := h.requireAuthenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	var req changePasswordRequest
+	if !DecodeAndValidate(w, r, &req) {
+		return
+	}
+	if req.CurrentPassword == "" {
+		WriteRequestError(w, ValidationError("currentPassword is required"))
+		return
+	}
+	if len(req.NewPassword) < 8 {
+		WriteRequestError(w, ValidationError("newPassword must be at least 8 characters"))
+		return
+	}
+	if _, err := h.Store.AuthenticateUser(user.Email, req.CurrentPassword); err != nil {
+		h.auditAuth(r, auth.AuditEvent{Action: auth.AuditActionPasswordFailure, ActorID: user.ID, UserID: user.ID, Reason: auth.AuditReasonInvalidCredentials})
+		WriteRequestError(w, RequestError{Status: http.StatusUnauthorized, CodeVal: "invalid_credentials", Message: "current password is incorrect", Err: err})
+		return
+	}
+	updated, err := h.Store.SetUserPassword(user.ID, req.NewPassword)
+	if err != nil {
+		writeStorageError(w, http.StatusInternalServerError, err)
+		return
+	}
+	h.auditAuth(r, auth.AuditEvent{Action: auth.AuditActionPasswordChange, ActorID: updated.ID, UserID: updated.ID})
+	h.sendPasswordChangedEmail(r, updated)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// sendPasswordChangedEmail queues the security notice for a password change
+// unless the user turned off account emails. Delivery happens in the
+// background; failures to queue are logged rather than failing the change.
+func (h *Handler) sendPasswordChangedEmail(r *http.Request, user models.User) {
+	if h.Mail == nil || user.Email == "" {
+		return
+	}
+	prefs, err := h.Store.GetNotificationPreferences(user.ID)
+	if err != nil {
+		h.logger().Warn("load notification preferences for password email", "user_id", user.ID, "error", err)
+		prefs = models.DefaultNotificationPreferences(user.ID)
+	}
+	if !prefs.Allows(models.NotificationCategoryAccount, models.NotificationDeliveryEmail) {
+		return
+	}
+	msg, err := mail.Render(mail.TemplatePasswordChanged, user.Email, mail.PasswordChangedData{
+		DisplayName: user.DisplayName,
+		ChangedAt:   h.now(),
+		AccountURL:  absoluteURL(requestBaseURL(r), "/profile", nil),
+	})
+	if err != nil {
+		h.logger().Error("render password changed email", "user_id", user.ID, "error", err)
+		return
+	}
+	if err := h.Mail.Enqueue(msg); err != nil {
+		level := h.logger().Error
+		if errors.Is(err, mail.ErrRateLimited) {
+			level = h.logger().Warn
+		}
+		level("queue password changed email", "user_id", user.ID, "error", err)
+	}
+}
@@ -0,0 +1,131 @@
+package api
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/mail"
+	"bitriver-live/internal/observability/metrics"
+	"bitriver-live/internal/storage"
+)
+
+// blockingMailSender holds every delivery until release is closed.
+type blockingMailSender struct {
+	release chan struct{}
+	mu      sync.Mutex
+	sent    []mail.Message
+}
+
+func (s *blockingMailSender) Send(ctx context.Context, msg mail.Message) error {
+	select {
+	case <-s.release:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+func (s *blockingMailSender) messages() []mail.Message {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]mail.Message(nil), s.sent...)
+}
+
+func TestChangePasswordQueuesSecurityEmail(t *testing.T) {
+	handler, store := newTestHandler(t)
+	user, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Ada", Email: "ada@example.com", Password: "old-password"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	sender := &blockingMailSender{release: make(chan struct{})}
+	handler.Mail = mail.NewQueue(mail.QueueConfig{Sender: sender, Metrics: metrics.New()})
+	defer handler.Mail.Shutdown(context.Background())
+
+	change := func(body string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(http.MethodPost, "/api/users/me/password", strings.NewReader(body)), user)
+		rec := httptest.NewRecorder()
+		handler.UserByID(rec, req)
+		return rec
+	}
+
+	rec := change(`{"currentPassword":"wrong-password","newPassword":"new-password"}`)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a wrong current password, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if resp := decodeAPIError(t, rec.Body.Bytes()); resp.Error.Code != "invalid_credentials" {
+		t.Fatalf("expected invalid_credentials, got %q", resp.Error.Code)
+	}
+	if rec := change(`{"currentPassword":"old-password","newPassword":"short"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a short password, got %d", rec.Code)
+	}
+
+	// The sender is blocked, so the handler only returns promptly if it
+	// leaves delivery to the queue.
+	done := make(chan *httptest.ResponseRecorder, 1)
+	go func() { done <- change(`{"currentPassword":"old-password","newPassword":"new-password"}`) }()
+	select {
+	case rec := <-done:
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("expected 204, got %d: %s", rec.Code, rec.Body.String())
+		}
+	case <-time.After(2 * time.Second):
+		t.Fatal("expected the password change to return without waiting for the email")
+	}
+	if _, err := store.AuthenticateUser("ada@example.com", "new-password"); err != nil {
+		t.Fatalf("expected the new password to work: %v", err)
+	}
+	if sent := sender.messages(); len(sent) != 0 {
+		t.Fatalf("expected delivery to wait for the sender, got %d messages", len(sent))
+	}
+
+	close(sender.release)
+	if err := handler.Mail.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	sent := sender.messages()
+	if len(sent) != 1 {
+		t.Fatalf("expected one security email, got %d", len(sent))
+	}
+	if sent[0].To != "ada@example.com" || sent[0].Subject != "Your BitRiver Live password was changed" {
+		t.Fatalf("unexpected email %+v", sent[0])
+	}
+	if !strings.Contains(sent[0].Text, "http://example.com/profile") {
+		t.Fatalf("expected a link back to the account, got %s", sent[0].Text)
+	}
+}
+
+func TestChangePasswordRespectsAccountEmailPreference(t *testing.T) {
+	handler, store := newTestHandler(t)
+	user, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Ada", Email: "ada@example.com", Password: "old-password"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	off := false
+	if _, err := store.UpdateNotificationPreferences(user.ID, storage.NotificationPreferencesUpdate{Account: &storage.NotificationDeliveryUpdate{Email: &off}}); err != nil {
+		t.Fatalf("UpdateNotificationPreferences: %v", err)
+	}
+	sender := &blockingMailSender{release: make(chan struct{})}
+	close(sender.release)
+	handler.Mail = mail.NewQueue(mail.QueueConfig{Sender: sender, Metrics: metrics.New()})
+
+	req := withUser(httptest.NewRequest(http.MethodPost, "/api/users/me/password", strings.NewReader(`{"currentPassword":"old-password","newPassword":"new-password"}`)), user)
+	rec := httptest.NewRecorder()
+	handler.UserByID(rec, req)
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if err := handler.Mail.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if sent := sender.messages(); len(sent) != 0 {
+		t.Fatalf("expected no email with account emails turned off, got %d", len(sent))
+	}
+}
@@ -0,0 +1,459 @@
+package api
+
+import (
+	"context"
+	"go/ast"
+	"go/parser"
+	"go/token"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/authz"
+	"bitriver-live/internal/chat"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/observability/logging"
+	"bitriver-live/internal/storage"
+)
+
+// permissionPersonas are the actors every protected endpoint is exercised
+// with. "owner" is the creator who owns the fixture channel; "creator" is a
+// creator without any relation to it; "editor" holds an editor grant on it.
+var permissionPersonas = []string{"admin", "owner", "creator", "moderator", "editor", "viewer"}
+
+type permissionFixture struct {
+	handler   *Handler
+	store     *storage.Storage
+	users     map[string]models.User
+	target    models.User
+	channel   models.Channel
+	recording models.Recording
+	upload    models.Upload
+	message   models.ChatMessage
+	report    models.ChatReport
+}
+
+func newPermissionFixture(t *testing.T) permissionFixture {
+	t.Helper()
+	handler, store := newTestHandler(t)
+	handler.ChatGateway = chat.NewGateway(chat.GatewayConfig{Queue: chat.NewMemoryQueue(16), Store: store})
+
+	roles := map[string][]string{
+		"admin":     {authz.RoleAdmin},
+		"owner":     {authz.RoleCreator},
+		"creator":   {authz.RoleCreator},
+		"moderator": {authz.RoleModerator},
+		"editor":    {authz.RoleEditor},
+		"viewer":    nil,
+	}
+	f := permissionFixture{handler: handler, store: store, users: make(map[string]models.User)}
+	for _, persona := range permissionPersonas {
+		user, err := store.CreateUser(storage.CreateUserParams{
+			DisplayName: persona,
+			Email:       persona + "@example.com",
+			Roles:       roles[persona],
+		})
+		if err != nil {
+			t.Fatalf("CreateUser %s: %v", persona, err)
+		}
+		f.users[persona] = user
+	}
+	target, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Target", Email: "target@example.com", Roles: []string{authz.RoleEditor}})
+	if err != nil {
+		t.Fatalf("CreateUser target: %v", err)
+	}
+	f.target = target
+	bio := "Target profile"
+	if _, err := store.UpsertProfile(target.ID, storage.ProfileUpdate{Bio: &bio}); err != nil {
+		t.Fatalf("UpsertProfile target: %v", err)
+	}
+
+	channel, err := store.CreateChannel(f.users["owner"].ID, "Studio", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	f.channel = channel
+	if _, err := store.GrantChannelEditor(channel.ID, f.users["editor"].ID, f.users["owner"].ID, nil); err != nil {
+		t.Fatalf("GrantChannelEditor: %v", err)
+	}
+
+	if _, err := store.StartStream(channel.ID, []string{"720p"}); err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	if _, err := store.StopStream(channel.ID, 3); err != nil {
+		t.Fatalf("StopStream: %v", err)
+	}
+	recordings, err := store.ListRecordings(channel.ID, true)
+	if err != nil || len(recordings) == 0 {
+		t.Fatalf("ListRecordings: %v (count %d)", err, len(recordings))
+	}
+	f.recording = recordings[0]
+
+	upload, err := store.CreateUpload(storage.CreateUploadParams{ChannelID: channel.ID, Title: "Upload", Filename: "upload.mp4"})
+	if err != nil {
+		t.Fatalf("CreateUpload: %v", err)
+	}
+	f.upload = upload
+
+	message, err := store.CreateChatMessage(channel.ID, target.ID, "hello", "")
+	if err != nil {
+		t.Fatalf("CreateChatMessage: %v", err)
+	}
+	f.message = message
+	report, err := store.CreateChatReport(channel.ID, f.users["viewer"].ID, target.ID, "spam", message.ID, "")
+	if err != nil {
+		t.Fatalf("CreateChatReport: %v", err)
+	}
+	f.report = report
+	return f
+}
+
+type permissionCase struct {
+	name string
+	// guards lists the functions whose permission checks this case exercises.
+	guards  []string
+	method  string
+	path    func(f permissionFixture) string
+	body    func(f permissionFixture) string
+	serve   func(h *Handler) http.HandlerFunc
+	allowed []string
+	// prepare, when set, adjusts the fixture before the request is served.
+	prepare func(t *testing.T, f permissionFixture)
+	// visible, when set, marks endpoints that filter rather than refuse:
+	// every persona gets a 200 and visible reports whether the protected
+	// data was included.
+	visible func(f permissionFixture, body []byte) bool
+}
+
+func channelPath(suffix string) func(f permissionFixture) string {
+	return func(f permissionFixture) string { return "/api/channels/" + f.channel.ID + suffix }
+}
+
+func recordingPath(suffix string) func(f permissionFixture) string {
+	return func(f permissionFixture) string { return "/api/recordings/" + f.recording.ID + suffix }
+}
+
+func staticString(value string) func(f permissionFixture) string {
+	return func(permissionFixture) string { return value }
+}
+
+func permissionCases() []permissionCase {
+	users := func(h *Handler) http.HandlerFunc { return h.Users }
+	userByID := func(h *Handler) http.HandlerFunc { return h.UserByID }
+	channels := func(h *Handler) http.HandlerFunc { return h.Channels }
+	channelByID := func(h *Handler) http.HandlerFunc { return h.ChannelByID }
+	recordingByID := func(h *Handler) http.HandlerFunc { return h.RecordingByID }
+	uploads := func(h *Handler) http.HandlerFunc { return h.Uploads }
+	uploadByID := func(h *Handler) http.HandlerFunc { return h.UploadByID }
+	targetPath := func(prefix string) func(f permissionFixture) string {
+		return func(f permissionFixture) string { return prefix + f.target.ID }
+	}
+	uploadPath := func(f permissionFixture) string { return "/api/uploads/" + f.upload.ID }
+
+	adminOnly := []string{"admin"}
+	channelManagers := []string{"admin", "owner"}
+	chatModerators := []string{"admin", "owner", "moderator"}
+	mediaManagers := []string{"admin", "owner", "editor"}
+	matureChannel := func(t *testing.T, f permissionFixture) {
+		mature := true
+		if _, err := f.store.UpdateChannel(f.channel.ID, storage.ChannelUpdate{MatureContent: &mature}); err != nil {
+			t.Fatalf("UpdateChannel: %v", err)
+		}
+	}
+	bannedTargetAppeal := func(t *testing.T, f permissionFixture) {
+		ban := chat.ModerationEvent{Action: chat.ModerationActionBan, ChannelID: f.channel.ID, ActorID: f.channel.OwnerID, TargetID: f.target.ID}
+		if err := f.store.ApplyChatEvent(chat.Event{Type: chat.EventTypeModeration, Moderation: &ban}); err != nil {
+			t.Fatalf("ApplyChatEvent: %v", err)
+		}
+		if _, err := f.store.CreateChatAppeal(f.channel.ID, f.target.ID, "please"); err != nil {
+			t.Fatalf("CreateChatAppeal: %v", err)
+		}
+	}
+	publishedMatureRecording := func(t *testing.T, f permissionFixture) {
+		matureChannel(t, f)
+		if _, err := f.store.PublishRecording(f.recording.ID); err != nil {
+			t.Fatalf("PublishRecording: %v", err)
+		}
+	}
+
+	return []permissionCase{
+		{name: "list users", guards: []string{"Users"}, method: http.MethodGet, path: staticString("/api/users"), serve: users, allowed: adminOnly},
+		{name: "create user", guards: []string{"Users"}, method: http.MethodPost, path: staticString("/api/users"), body: staticString(`{"displayName":"New","email":"new@example.com"}`), serve: users, allowed: adminOnly},
+		{name: "get other user", guards: []string{"UserByID"}, method: http.MethodGet, path: targetPath("/api/users/"), serve: userByID, allowed: adminOnly},
+		{name: "update user", guards: []string{"UserByID"}, method: http.MethodPatch, path: targetPath("/api/users/"), body: staticString(`{"displayName":"Renamed"}`), serve: userByID, allowed: adminOnly},
+		{name: "delete user", guards: []string{"UserByID"}, method: http.MethodDelete, path: targetPath("/api/users/"), serve: userByID, allowed: adminOnly},
+		{name: "update other profile", guards: []string{"ProfileByID"}, method: http.MethodPut, path: targetPath("/api/profiles/"), body: staticString(`{"bio":"hello"}`), serve: func(h *Handler) http.HandlerFunc { return h.ProfileByID }, allowed: adminOnly},
+		{name: "set user stream limit", guards: []string{"adminUserStreamLimit"}, method: http.MethodPut, path: func(f permissionFixture) string { return "/api/admin/users/" + f.target.ID + "/stream-limit" }, body: staticString(`{"maxConcurrentStreams":2}`), serve: func(h *Handler) http.HandlerFunc { return h.AdminUserByID }, allowed: adminOnly},
+		{name: "impersonate user", guards: []string{"AdminUserByID", "canImpersonate"}, method: http.MethodPost, path: func(f permissionFixture) string { return "/api/admin/users/" + f.target.ID + "/impersonate" }, serve: func(h *Handler) http.HandlerFunc { return h.AdminUserByID }, allowed: adminOnly},
+		{name: "export channels", guards: []string{"AdminChannelsExport"}, method: http.MethodGet, path: staticString("/api/admin/channels/export"), serve: func(h *Handler) http.HandlerFunc { return h.AdminChannelsExport }, allowed: adminOnly},
+		{name: "batch update channels", guards: []string{"AdminChannelsBatch"}, method: http.MethodPost, path: staticString("/api/admin/channels/batch"), body: func(f permissionFixture) string { return `{"channelIds":["` + f.channel.ID + `"],"category":"music"}` }, serve: func(h *Handler) http.HandlerFunc { return h.AdminChannelsBatch }, allowed: adminOnly},
+		{name: "refresh ingest health", guards: []string{"refreshIngestHealth"}, method: http.MethodGet, path: staticString("/healthz?refresh=true"), prepare: func(t *testing.T, f permissionFixture) {
+			f.handler.IngestHealthPoller = &countingRefresher{}
+		}, serve: func(h *Handler) http.HandlerFunc { return h.Health }, allowed: adminOnly},
+		{name: "background component health", guards: []string{"AdminComponentHealth"}, method: http.MethodGet, path: staticString("/api/admin/health/components"), serve: func(h *Handler) http.HandlerFunc { return h.AdminComponentHealth }, allowed: adminOnly},
+		{name: "create badge", guards: []string{"AdminBadges"}, method: http.MethodPost, path: staticString("/api/admin/badges"), body: staticString(`{"slug":"partner","label":"Partner"}`), serve: func(h *Handler) http.HandlerFunc { return h.AdminBadges }, allowed: adminOnly},
+		{name: "create feature flag", guards: []string{"AdminFeatureFlags"}, method: http.MethodPost, path: staticString("/api/admin/feature-flags"), body: staticString(`{"key":"new-player"}`), serve: func(h *Handler) http.HandlerFunc { return h.AdminFeatureFlags }, allowed: adminOnly},
+		{name: "read feature flag", guards: []string{"AdminFeatureFlagByKey"}, method: http.MethodGet, path: staticString("/api/admin/feature-flags/new-player"), serve: func(h *Handler) http.HandlerFunc { return h.AdminFeatureFlagByKey }, allowed: adminOnly},
+		{name: "grant badge", guards: []string{"AdminBadgeBySlug"}, method: http.MethodPut, path: targetPath("/api/admin/badges/verified/users/"), serve: func(h *Handler) http.HandlerFunc { return h.AdminBadgeBySlug }, allowed: adminOnly},
+		{name: "admin platform stats", guards: []string{"AdminPlatformStats"}, method: http.MethodGet, path: staticString("/api/admin/stats"), prepare: func(t *testing.T, f permissionFixture) {
+			if _, err := f.store.RollupPlatformStats(context.Background(), time.Now()); err != nil {
+				t.Fatalf("RollupPlatformStats: %v", err)
+			}
+		}, serve: func(h *Handler) http.HandlerFunc { return h.AdminPlatformStats }, allowed: adminOnly},
+		{name: "update log levels", guards: []string{"AdminLogging"}, method: http.MethodPut, path: staticString("/api/admin/logging"), body: staticString(`{"level":"debug"}`), serve: func(h *Handler) http.HandlerFunc {
+			h.LogLevels = logging.NewLevelController("info")
+			return h.AdminLogging
+		}, allowed: adminOnly},
+		{name: "analytics overview", guards: []string{"AnalyticsOverview"}, method: http.MethodGet, path: staticString("/api/analytics/overview"), serve: func(h *Handler) http.HandlerFunc { return h.AnalyticsOverview }, allowed: adminOnly},
+
+		{name: "list owner channels", guards: []string{"Channels"}, method: http.MethodGet, path: func(f permissionFixture) string { return "/api/channels?ownerId=" + f.channel.OwnerID }, serve: channels, allowed: channelManagers},
+		{name: "create channel", guards: []string{"Channels"}, method: http.MethodPost, path: staticString("/api/channels"), body: staticString(`{"title":"Mine"}`), serve: channels, allowed: []string{"admin", "owner", "creator"}},
+		{name: "create channel for owner", guards: []string{"Channels"}, method: http.MethodPost, path: staticString("/api/channels"), body: func(f permissionFixture) string { return `{"title":"Theirs","ownerId":"` + f.channel.OwnerID + `"}` }, serve: channels, allowed: channelManagers},
+		{name: "get channel details", guards: []string{"writeChannel"}, method: http.MethodGet, path: channelPath(""), serve: channelByID, allowed: channelManagers, visible: func(f permissionFixture, body []byte) bool {
+			return strings.Contains(string(body), "recordingPolicy")
+		}},
+		{name: "get channel details by slug", guards: []string{"writeChannel"}, method: http.MethodGet, path: func(f permissionFixture) string { return "/api/channels/by-slug/" + f.channel.Slug }, serve: channelByID, allowed: channelManagers, visible: func(f permissionFixture, body []byte) bool {
+			return strings.Contains(string(body), "recordingPolicy")
+		}},
+		{name: "change channel slug", guards: []string{"ChannelByID"}, method: http.MethodPatch, path: channelPath(""), body: staticString(`{"slug":"renamed-channel"}`), serve: channelByID, allowed: channelManagers},
+		{name: "update channel", guards: []string{"ChannelByID"}, method: http.MethodPatch, path: channelPath(""), body: staticString(`{"title":"Renamed"}`), serve: channelByID, allowed: channelManagers},
+		{name: "delete channel", guards: []string{"ChannelByID"}, method: http.MethodDelete, path: channelPath(""), serve: channelByID, allowed: channelManagers},
+		{name: "rotate stream key", guards: []string{"handleStreamRoutes"}, method: http.MethodPost, path: channelPath("/stream/rotate"), serve: channelByID, allowed: channelManagers},
+		{name: "rotate stream key and stop", guards: []string{"handleStreamRoutes"}, method: http.MethodPost, path: channelPath("/stream/rotate-and-stop"), serve: channelByID, allowed: channelManagers},
+		{name: "list security events", guards: []string{"handleStreamRoutes"}, method: http.MethodGet, path: channelPath("/stream/security-events"), serve: channelByID, allowed: channelManagers},
+		{name: "list sessions", guards: []string{"handleChannelSessions"}, method: http.MethodGet, path: channelPath("/sessions"), serve: channelByID, allowed: channelManagers},
+		{name: "export channel analytics", guards: []string{"handleChannelAnalytics"}, method: http.MethodGet, path: channelPath("/analytics/export?dataset=follows"), serve: channelByID, allowed: channelManagers},
+		{name: "channel dashboard", guards: []string{"handleChannelDashboard"}, method: http.MethodGet, path: channelPath("/dashboard"), serve: channelByID, allowed: chatModerators},
+		{name: "list editors", guards: []string{"handleChannelEditors"}, method: http.MethodGet, path: channelPath("/editors"), serve: channelByID, allowed: channelManagers},
+		{name: "grant editor", guards: []string{"handleChannelEditors"}, method: http.MethodPost, path: channelPath("/editors"), body: func(f permissionFixture) string { return `{"userId":"` + f.target.ID + `"}` }, serve: channelByID, allowed: channelManagers},
+		{name: "revoke editor", guards: []string{"handleChannelEditors"}, method: http.MethodDelete, path: func(f permissionFixture) string {
+			return "/api/channels/" + f.channel.ID + "/editors/" + f.users["editor"].ID
+		}, serve: channelByID, allowed: channelManagers},
+		{name: "list moderators", guards: []string{"handleChannelModerators"}, method: http.MethodGet, path: channelPath("/moderators"), serve: channelByID, allowed: channelManagers},
+		{name: "grant moderator", guards: []string{"handleChannelModerators"}, method: http.MethodPost, path: channelPath("/moderators"), body: func(f permissionFixture) string { return `{"userId":"` + f.target.ID + `","duration":"1h"}` }, serve: channelByID, allowed: channelManagers},
+		{name: "revoke moderator", guards: []string{"handleChannelModerators"}, method: http.MethodDelete, path: func(f permissionFixture) string {
+			return "/api/channels/" + f.channel.ID + "/moderators/" + f.target.ID
+		}, serve: channelByID, allowed: channelManagers},
+		{name: "list restream targets", guards: []string{"handleRestreamTargets"}, method: http.MethodGet, path: channelPath("/restreams"), serve: channelByID, allowed: channelManagers},
+		{name: "list co-stream invites", guards: []string{"handleCoStreamInvites"}, method: http.MethodGet, path: channelPath("/costream/invites"), serve: channelByID, allowed: channelManagers},
+		{name: "restream status", guards: []string{"handleStreamRoutes"}, method: http.MethodGet, path: channelPath("/stream/restreams"), serve: channelByID, allowed: channelManagers},
+		{name: "recover stuck stream", guards: []string{"handleStreamRoutes"}, method: http.MethodPost, path: channelPath("/stream/recover"), serve: channelByID, allowed: channelManagers},
+		{name: "create schedule entry", guards: []string{"handleChannelSchedule"}, method: http.MethodPost, path: channelPath("/schedule"), body: staticString(`{"title":"Weekly","startsAt":"2030-01-07T18:00:00Z","durationMinutes":60}`), serve: channelByID, allowed: channelManagers},
+
+		{name: "delete chat message", guards: []string{"handleChatRoutes"}, method: http.MethodDelete, path: func(f permissionFixture) string { return "/api/channels/" + f.channel.ID + "/chat/" + f.message.ID }, serve: channelByID, allowed: chatModerators},
+		{name: "post chat as another user", guards: []string{"handleChatRoutes"}, method: http.MethodPost, path: channelPath("/chat"), body: func(f permissionFixture) string { return `{"userId":"` + f.target.ID + `","content":"hi"}` }, serve: channelByID, allowed: adminOnly},
+		{name: "apply chat moderation", guards: []string{"handleChatModeration"}, method: http.MethodPost, path: channelPath("/chat/moderation"), body: func(f permissionFixture) string { return `{"action":"ban","targetId":"` + f.target.ID + `"}` }, serve: channelByID, allowed: chatModerators},
+		{name: "list chat restrictions", guards: []string{"handleChatModeration"}, method: http.MethodGet, path: channelPath("/chat/moderation/restrictions"), serve: channelByID, allowed: chatModerators},
+		{name: "list chat reports", guards: []string{"handleChatReports"}, method: http.MethodGet, path: channelPath("/chat/reports"), serve: channelByID, allowed: chatModerators},
+		{name: "resolve chat report", guards: []string{"handleChatReports"}, method: http.MethodPost, path: func(f permissionFixture) string {
+			return "/api/channels/" + f.channel.ID + "/chat/reports/" + f.report.ID + "/resolve"
+		}, body: staticString(`{"resolution":"handled"}`), serve: channelByID, allowed: chatModerators},
+		{name: "list chat appeals", guards: []string{"handleChatAppeals"}, method: http.MethodGet, path: channelPath("/chat/appeals"), serve: channelByID, allowed: chatModerators},
+		{name: "resolve chat appeal", guards: []string{"handleChatAppeals"}, method: http.MethodPost, prepare: bannedTargetAppeal, path: func(f permissionFixture) string {
+			appeals, _ := f.store.ListChatAppeals(f.channel.ID, false)
+			return "/api/channels/" + f.channel.ID + "/chat/appeals/" + appeals[0].ID + "/resolve"
+		}, body: staticString(`{"decision":"reject"}`), serve: channelByID, allowed: chatModerators},
+		{name: "pin chat message", guards: []string{"handleChatPin"}, method: http.MethodPost, path: channelPath("/chat/pin"), body: staticString(`{"content":"Giveaway at 8pm"}`), serve: channelByID, allowed: chatModerators},
+		{name: "moderation queue", guards: []string{"ModerationQueue"}, method: http.MethodGet, path: staticString("/api/moderation/queue"), serve: func(h *Handler) http.HandlerFunc { return h.ModerationQueue }, allowed: []string{"admin", "moderator"}},
+		{name: "resolve moderation flag", guards: []string{"ModerationQueueByID"}, method: http.MethodPost, path: func(f permissionFixture) string { return "/api/moderation/queue/" + f.report.ID }, body: staticString(`{"resolution":"handled"}`), serve: func(h *Handler) http.HandlerFunc { return h.ModerationQueueByID }, allowed: []string{"admin", "moderator"}},
+
+		{name: "list stream markers", guards: []string{"handleStreamMarkers"}, method: http.MethodGet, path: channelPath("/stream/markers"), serve: channelByID, allowed: chatModerators},
+		{name: "create stream marker", guards: []string{"handleStreamMarkers"}, method: http.MethodPost, path: channelPath("/stream/markers"), body: staticString(`{"label":"Highlight"}`), serve: channelByID, allowed: chatModerators},
+
+		{name: "chatter stats", guards: []string{"handleChatters"}, method: http.MethodGet, path: channelPath("/chatters/stats"), serve: channelByID, allowed: chatModerators},
+
+		{name: "list followers", guards: []string{"handleChannelFollowers"}, method: http.MethodGet, path: channelPath("/followers"), serve: channelByID, allowed: chatModerators},
+		{name: "moderation history", guards: []string{"handleModerationHistory"}, method: http.MethodGet, path: func(f permissionFixture) string {
+			return "/api/channels/" + f.channel.ID + "/users/" + f.target.ID + "/moderation-history"
+		}, serve: channelByID, allowed: chatModerators},
+
+		{name: "channel storage", guards: []string{"handleChannelStorage"}, method: http.MethodGet, path: channelPath("/storage"), serve: channelByID, allowed: channelManagers},
+		{name: "set channel storage quota", guards: []string{"handleChannelStorage"}, method: http.MethodPatch, path: channelPath("/storage"), body: staticString(`{"quotaBytes":1000}`), serve: channelByID, allowed: adminOnly},
+		{name: "list tips", guards: []string{"handleTipsRoutes"}, method: http.MethodGet, path: channelPath("/monetization/tips"), serve: channelByID, allowed: channelManagers},
+		{name: "list subscriptions", guards: []string{"handleSubscriptionsRoutes"}, method: http.MethodGet, path: channelPath("/monetization/subscriptions"), serve: channelByID, allowed: channelManagers},
+
+		{name: "search profiles by email", guards: []string{"listProfilesPage"}, method: http.MethodGet, path: staticString("/api/profiles?q=target@example.com"), serve: func(h *Handler) http.HandlerFunc { return h.Profiles }, allowed: []string{"admin"}, visible: func(f permissionFixture, body []byte) bool {
+			return strings.Contains(string(body), f.target.ID)
+		}},
+		{name: "list recordings", guards: []string{"Recordings"}, method: http.MethodGet, path: func(f permissionFixture) string { return "/api/recordings?channelId=" + f.channel.ID }, serve: func(h *Handler) http.HandlerFunc { return h.Recordings }, allowed: mediaManagers, visible: func(f permissionFixture, body []byte) bool {
+			return strings.Contains(string(body), f.recording.ID)
+		}},
+		{name: "get unpublished recording", guards: []string{"RecordingByID"}, method: http.MethodGet, path: recordingPath(""), serve: recordingByID, allowed: mediaManagers},
+		{name: "publish recording", guards: []string{"RecordingByID"}, method: http.MethodPost, path: recordingPath("/publish"), serve: recordingByID, allowed: mediaManagers},
+		{name: "list unpublished clips", guards: []string{"RecordingByID"}, method: http.MethodGet, path: recordingPath("/clips"), serve: recordingByID, allowed: mediaManagers},
+		{name: "create clip", guards: []string{"RecordingByID"}, method: http.MethodPost, path: recordingPath("/clips"), body: staticString(`{"title":"Clip","startSeconds":0,"endSeconds":1}`), serve: recordingByID, allowed: mediaManagers},
+		{name: "report unpublished recording", guards: []string{"handleRecordingReport"}, method: http.MethodPost, path: recordingPath("/report"), body: staticString(`{"category":"other","reason":"spam"}`), serve: recordingByID, allowed: mediaManagers},
+		{name: "unpublished recording chat", guards: []string{"RecordingByID"}, method: http.MethodGet, path: recordingPath("/chat"), serve: recordingByID, allowed: mediaManagers},
+		{name: "unpublished recording playback", guards: []string{"authorizeRecordingPlayback"}, method: http.MethodGet, path: recordingPath("/playback"), serve: recordingByID, allowed: mediaManagers},
+		{name: "refresh unpublished recording playback", guards: []string{"authorizeRecordingPlayback"}, method: http.MethodPost, path: recordingPath("/playback/refresh"), serve: recordingByID, allowed: mediaManagers},
+		{name: "edit recording", guards: []string{"updateRecording"}, method: http.MethodPatch, path: recordingPath(""), body: staticString(`{"description":"Edited","tags":["archive"]}`), serve: recordingByID, allowed: mediaManagers},
+		{name: "set recording chapters", guards: []string{"setRecordingChapters"}, method: http.MethodPut, path: recordingPath("/chapters"), body: staticString(`{"chapters":[{"offsetSeconds":0,"title":"Start"}]}`), serve: recordingByID, allowed: mediaManagers},
+		{name: "batch edit recordings", guards: []string{"handleChannelRecordings"}, method: http.MethodPost, path: channelPath("/recordings/batch"), body: func(f permissionFixture) string {
+			return `{"recordingIds":["` + f.recording.ID + `"],"addTags":["archive"]}`
+		}, serve: channelByID, allowed: mediaManagers},
+		{name: "delete recording", guards: []string{"RecordingByID"}, method: http.MethodDelete, path: recordingPath(""), serve: recordingByID, allowed: mediaManagers},
+		{name: "watch mature published recording", guards: []string{"recordingAgeGate"}, method: http.MethodGet, path: recordingPath(""), prepare: publishedMatureRecording, serve: recordingByID, allowed: []string{"admin", "owner", "moderator", "editor"}},
+		{name: "mature channel vods", guards: []string{"bypassesPlaybackGates"}, method: http.MethodGet, path: channelPath("/vods"), prepare: matureChannel, serve: channelByID, allowed: []string{"admin", "owner", "moderator"}, visible: func(f permissionFixture, body []byte) bool {
+			return !strings.Contains(string(body), "playbackWithheldReason")
+		}},
+		{name: "list uploads", guards: []string{"Uploads"}, method: http.MethodGet, path: func(f permissionFixture) string { return "/api/uploads?channelId=" + f.channel.ID }, serve: uploads, allowed: mediaManagers},
+		{name: "create upload", guards: []string{"createUploadEntry"}, method: http.MethodPost, path: staticString("/api/uploads"), body: func(f permissionFixture) string {
+			return `{"channelId":"` + f.channel.ID + `","title":"New","filename":"new.mp4"}`
+		}, serve: uploads, allowed: mediaManagers},
+		{name: "get upload", guards: []string{"UploadByID"}, method: http.MethodGet, path: uploadPath, serve: uploadByID, allowed: mediaManagers},
+		{name: "delete upload", guards: []string{"UploadByID"}, method: http.MethodDelete, path: uploadPath, serve: uploadByID, allowed: mediaManagers},
+	}
+}
+
+func TestPermissionMatrix(t *testing.T) {
+	for _, tc := range permissionCases() {
+		tc := tc
+		allowed := make(map[string]bool, len(tc.allowed))
+		for _, persona := range tc.allowed {
+			allowed[persona] = true
+		}
+		for _, persona := range permissionPersonas {
+			persona := persona
+			t.Run(tc.name+"/"+persona, func(t *testing.T) {
+				f := newPermissionFixture(t)
+				if tc.prepare != nil {
+					tc.prepare(t, f)
+				}
+				var body *strings.Reader
+				if tc.body != nil {
+					body = strings.NewReader(tc.body(f))
+				} else {
+					body = strings.NewReader("")
+				}
+				req := httptest.NewRequest(tc.method, tc.path(f), body)
+				if tc.body != nil {
+					req.Header.Set("Content-Type", "application/json")
+				}
+				req = withUser(req, f.users[persona])
+				rec := httptest.NewRecorder()
+				tc.serve(f.handler)(rec, req)
+
+				if tc.visible != nil {
+					if rec.Code != http.StatusOK {
+						t.Fatalf("expected %s to receive 200, got %d: %s", persona, rec.Code, rec.Body.String())
+					}
+					if got := tc.visible(f, rec.Body.Bytes()); got != allowed[persona] {
+						t.Fatalf("expected protected data visible=%v for %s, got %v", allowed[persona], persona, got)
+					}
+					return
+				}
+				if allowed[persona] {
+					if rec.Code == http.StatusUnauthorized || rec.Code == http.StatusForbidden || rec.Code >= http.StatusInternalServerError {
+						t.Fatalf("expected %s to be allowed, got %d: %s", persona, rec.Code, rec.Body.String())
+					}
+					return
+				}
+				if rec.Code != http.StatusForbidden {
+					t.Fatalf("expected %s to be forbidden, got %d: %s", persona, rec.Code, rec.Body.String())
+				}
+			})
+		}
+	}
+}
+
+// permissionCheckCalls are the helpers that gate access. Any function calling
+// one of them must be exercised by at least one permissionCases entry.
+var permissionCheckCalls = map[string]bool{
+	"requirePermission":          true,
+	"ensureChannelAccess":        true,
+	"canManageChannel":           true,
+	"canViewChannelDetails":      true,
+	"canManageChannelMedia":      true,
+	"canModerateChannel":         true,
+	"canViewChannelMonetization": true,
+	"Has":                        true,
+	"CanModerateChannel":         true,
+	"CanResolveChannelReports":   true,
+}
+
+func TestPermissionMatrixCoversGuardedHandlers(t *testing.T) {
+	guarded := permissionGuardedFunctions(t)
+
+	covered := make(map[string]bool)
+	for _, tc := range permissionCases() {
+		for _, guard := range tc.guards {
+			covered[guard] = true
+		}
+	}
+
+	var missing []string
+	for name := range guarded {
+		if !covered[name] {
+			missing = append(missing, name)
+		}
+	}
+	sort.Strings(missing)
+	if len(missing) > 0 {
+		t.Fatalf("functions performing permission checks without a permission matrix case: %s", strings.Join(missing, ", "))
+	}
+
+	for _, tc := range permissionCases() {
+		for _, guard := range tc.guards {
+			if !guarded[guard] {
+				t.Errorf("case %q lists %s, which performs no permission check", tc.name, guard)
+			}
+		}
+	}
+}
+
+// permissionGuardedFunctions parses the package sources and returns the names
+// of functions that call a permission helper.
+func permissionGuardedFunctions(t *testing.T) map[string]bool {
+	t.Helper()
+	paths, err := filepath.Glob("*.go")
+	if err != nil {
+		t.Fatalf("glob sources: %v", err)
+	}
+	fset := token.NewFileSet()
+	guarded := make(map[string]bool)
+	for _, path := range paths {
+		if strings.HasSuffix(path, "_test.go") {
+			continue
+		}
+		src, err := os.ReadFile(path)
+		if err != nil {
+			t.Fatalf("read %s: %v", path, err)
+		}
+		file, err := parser.ParseFile(fset, path, src, 0)
+		if err != nil {
+			t.Fatalf("parse %s: %v", path, err)
+		}
+		for _, decl := range file.Decls {
+			fn, ok := decl.(*ast.FuncDecl)
+			if !ok || fn.Body == nil || permissionCheckCalls[fn.Name.Name] {
+				continue
+			}
+			ast.Inspect(fn.Body, func(node ast.Node) bool {
+				call, ok := node.(*ast.CallExpr)
+				if !ok {
+					return true
+				}
+				var name string
+				switch fun := call.Fun.(type) {
+				case *ast.Ident:
+					name = fun.Name
+				case *ast.SelectorExpr:
+					name = fun.Sel.Name
+					if ident, ok := fun.X.(*ast.Ident); ok && ident.Name != "h" && ident.Name != "authz" {
+						name = ""
+					}
+				}
+				if permissionCheckCalls[name] {
+					guarded[fn.Name.Name] = true
+				}
+				return true
+			})
+		}
+	}
+	return guarded
+}
@@ -0,0 +1,285 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"net/http"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/ingest"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+const (
+	// playbackPreferencesCookie carries an anonymous viewer's playback
+	// preferences, signed so clients cannot forge arbitrary values.
+	playbackPreferencesCookie = "bitriver_playback"
+	// playbackPreferencesCookieTTL keeps the preference across visits.
+	playbackPreferencesCookieTTL = 365 * 24 * time.Hour
+)
+
+type playbackPreferencesResponse struct {
+	DefaultQuality   string  `json:"defaultQuality"`
+	PreferLowLatency bool    `json:"preferLowLatency"`
+	MutedAutoplay    bool    `json:"mutedAutoplay"`
+	UpdatedAt        *string `json:"updatedAt,omitempty"`
+}
+
+func newPlaybackPreferencesResponse(prefs models.PlaybackPreferences) playbackPreferencesResponse {
+	response := playbackPreferencesResponse{
+		DefaultQuality:   prefs.DefaultQuality,
+		PreferLowLatency: prefs.PreferLowLatency,
+		MutedAutoplay:    prefs.MutedAutoplay,
+	}
+	if prefs.UpdatedAt != nil {
+		updated := prefs.UpdatedAt.Format(time.RFC3339Nano)
+		response.UpdatedAt = &updated
+	}
+	return response
+}
+
+type updatePlaybackPreferencesRequest struct {
+	DefaultQuality   *string `json:"defaultQuality"`
+	PreferLowLatency *bool   `json:"preferLowLatency"`
+	MutedAutoplay    *bool   `json:"mutedAutoplay"`
+}
+
+func (r updatePlaybackPreferencesRequest) update() storage.PlaybackPreferencesUpdate {
+	return storage.PlaybackPreferencesUpdate{
+		DefaultQuality:   r.DefaultQuality,
+		PreferLowLatency: r.PreferLowLatency,
+		MutedAutoplay:    r.MutedAutoplay,
+	}
+}
+
+// playbackPreferencesError maps errors from reading or saving preferences.
+func playbackPreferencesError(err error) RequestError {
+	if errors.Is(err, storage.ErrInvalidPlaybackQuality) {
+		return ValidationError("defaultQuality must be one of auto, source, high, medium or low")
+	}
+	return RequestError{Status: http.StatusInternalServerError, Err: err}
+}
+
+// userPlaybackPreferences serves /api/users/me/playback-preferences. GET
+// returns the signed-in user's preferences, defaults included, and PATCH
+// changes only the fields present in the body.
+func (h *Handler) userPlaybackPreferences(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.requireAuthenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	h.storedPlaybackPreferences(user, w, r)
+}
+
+func (h *Handler) storedPlaybackPreferences(user models.User, w http.ResponseWriter, r *http.Request) {
+	switch r.Method {
+	case http.MethodGet:
+		prefs, err := h.Store.GetPlaybackPreferences(user.ID)
+		if err != nil {
+			WriteRequestError(w, playbackPreferencesError(err))
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		WriteJSON(w, http.StatusOK, newPlaybackPreferencesResponse(prefs))
+	case http.MethodPatch:
+		var req updatePlaybackPreferencesRequest
+		if !DecodeAndValidate(w, r, &req) {
+			return
+		}
+		prefs, err := h.Store.UpdatePlaybackPreferences(user.ID, req.update())
+		if err != nil {
+			WriteRequestError(w, playbackPreferencesError(err))
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		WriteJSON(w, http.StatusOK, newPlaybackPreferencesResponse(prefs))
+	default:
+		WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodPatch)
+	}
+}
+
+// PlaybackPreferences serves /api/playback-preferences for players that do
+// not know whether the viewer is signed in. Signed-in viewers read and
+// change their stored preferences. Anonymous viewers keep theirs in a signed
+// cookie, which PATCH rewrites and which needs CookieSigningKey.
+func (h *Handler) PlaybackPreferences(w http.ResponseWriter, r *http.Request) {
+	if user, ok := UserFromContext(r.Context()); ok {
+		h.storedPlaybackPreferences(user, w, r)
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		w.Header().Set("Cache-Control", "no-store")
+		WriteJSON(w, http.StatusOK, newPlaybackPreferencesResponse(h.cookiePlaybackPreferences(r)))
+	case http.MethodPatch:
+		if len(h.CookieSigningKey) == 0 {
+			WriteRequestError(w, RequestError{Status: http.StatusNotImplemented, CodeVal: "secret_key_unconfigured", Message: "saving preferences without an account requires a server secret key"})
+			return
+		}
+		var req updatePlaybackPreferencesRequest
+		if !DecodeAndValidate(w, r, &req) {
+			return
+		}
+		prefs := h.cookiePlaybackPreferences(r)
+		if err := req.update().Apply(&prefs); err != nil {
+			WriteRequestError(w, playbackPreferencesError(err))
+			return
+		}
+		now := h.now().UTC()
+		prefs.UpdatedAt = &now
+		value, err := h.encodePlaybackPreferencesCookie(prefs)
+		if err != nil {
+			writeStorageError(w, http.StatusInternalServerError, err)
+			return
+		}
+		policy := h.sessionCookiePolicy()
+		http.SetCookie(w, &http.Cookie{
+			Name:     playbackPreferencesCookie,
+			Value:    value,
+			Path:     "/",
+			Expires:  now.Add(playbackPreferencesCookieTTL),
+			MaxAge:   int(playbackPreferencesCookieTTL.Seconds()),
+			HttpOnly: true,
+			Secure:   policy.secure(r),
+			SameSite: policy.SameSite,
+		})
+		w.Header().Set("Cache-Control", "no-store")
+		WriteJSON(w, http.StatusOK, newPlaybackPreferencesResponse(prefs))
+	default:
+		WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodPatch)
+	}
+}
+
+// viewerPlaybackPreferences returns the preferences the watch page should
+// honour: the signed-in viewer's stored ones, else an anonymous viewer's
+// cookie, else the defaults.
+func (h *Handler) viewerPlaybackPreferences(r *http.Request, viewer *models.User) (models.PlaybackPreferences, error) {
+	if viewer != nil {
+		return h.Store.GetPlaybackPreferences(viewer.ID)
+	}
+	return h.cookiePlaybackPreferences(r), nil
+}
+
+// playbackPreferencesCookieValue is the cookie payload. Keys are short to
+// keep the cookie small.
+type playbackPreferencesCookieValue struct {
+	DefaultQuality   string `json:"q"`
+	PreferLowLatency bool   `json:"l,omitempty"`
+	MutedAutoplay    bool   `json:"m,omitempty"`
+	UpdatedAt        int64  `json:"t,omitempty"`
+}
+
+// playbackPreferencesCookieMAC signs payload with a key derived from
+// CookieSigningKey, so the secret itself never signs cookies directly.
+func (h *Handler) playbackPreferencesCookieMAC(payload string) []byte {
+	derive := hmac.New(sha256.New, h.CookieSigningKey)
+	derive.Write([]byte(playbackPreferencesCookie))
+	mac := hmac.New(sha256.New, derive.Sum(nil))
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+func (h *Handler) encodePlaybackPreferencesCookie(prefs models.PlaybackPreferences) (string, error) {
+	value := playbackPreferencesCookieValue{
+		DefaultQuality:   prefs.DefaultQuality,
+		PreferLowLatency: prefs.PreferLowLatency,
+		MutedAutoplay:    prefs.MutedAutoplay,
+	}
+	if prefs.UpdatedAt != nil {
+		value.UpdatedAt = prefs.UpdatedAt.Unix()
+	}
+	encoded, err := json.Marshal(value)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(encoded)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(h.playbackPreferencesCookieMAC(payload)), nil
+}
+
+// cookiePlaybackPreferences reads the anonymous viewer's preferences cookie.
+// A missing, unsigned, or tampered cookie yields the defaults.
+func (h *Handler) cookiePlaybackPreferences(r *http.Request) models.PlaybackPreferences {
+	prefs := models.DefaultPlaybackPreferences("")
+	if len(h.CookieSigningKey) == 0 {
+		return prefs
+	}
+	cookie, err := r.Cookie(playbackPreferencesCookie)
+	if err != nil {
+		return prefs
+	}
+	payload, signature, found := strings.Cut(cookie.Value, ".")
+	if !found {
+		return prefs
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil || !hmac.Equal(mac, h.playbackPreferencesCookieMAC(payload)) {
+		return prefs
+	}
+	decoded, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return prefs
+	}
+	var value playbackPreferencesCookieValue
+	if err := json.Unmarshal(decoded, &value); err != nil {
+		return prefs
+	}
+	quality, ok := models.NormalizePlaybackQuality(value.DefaultQuality)
+	if !ok {
+		return prefs
+	}
+	prefs.DefaultQuality = quality
+	prefs.PreferLowLatency = value.PreferLowLatency
+	prefs.MutedAutoplay = value.MutedAutoplay
+	if value.UpdatedAt > 0 {
+		updated := time.Unix(value.UpdatedAt, 0).UTC()
+		prefs.UpdatedAt = &updated
+	}
+	return prefs
+}
+
+// preferredRendition picks the rendition a player should start on for
+// quality. Auto leaves the choice to adaptive bitrate and returns "".
+// Source picks a rendition named "source", else the tallest one. Fixed
+// qualities pick the tallest rendition within their height cap, else the
+// shortest available. Heights are read from rendition names such as "720p"
+// and compared by their shorter side so vertical video maps the same way.
+func preferredRendition(quality string, renditions []renditionManifestResponse) string {
+	if quality == models.PlaybackQualityAuto || len(renditions) == 0 {
+		return ""
+	}
+	maxHeight, capped := models.PlaybackQualityMaxHeight(quality)
+	var tallest, shortest, best string
+	tallestHeight, shortestHeight, bestHeight := 0, 0, 0
+	for _, rendition := range renditions {
+		if quality == models.PlaybackQualitySource && strings.EqualFold(rendition.Name, "source") {
+			return rendition.Name
+		}
+		width, height, ok := ingest.RenditionDimensions(rendition.Name)
+		if !ok {
+			continue
+		}
+		height = min(width, height)
+		if height > tallestHeight {
+			tallest, tallestHeight = rendition.Name, height
+		}
+		if shortest == "" || height < shortestHeight {
+			shortest, shortestHeight = rendition.Name, height
+		}
+		if capped && height <= maxHeight && height > bestHeight {
+			best, bestHeight = rendition.Name, height
+		}
+	}
+	switch {
+	case !capped:
+		return tallest
+	case best != "":
+		return best
+	default:
+		return shortest
+	}
+}
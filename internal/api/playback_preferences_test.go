@@ -0,0 +1,283 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/auth"
+	"bitriver-live/internal/cache"
+	"bitriver-live/internal/ingest"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+// newPlaybackPreferencesFixture returns a handler over a live channel
+// offering a 1080p, 720p and 480p ladder.
+func newPlaybackPreferencesFixture(t *testing.T) (*Handler, storage.Repository, models.Channel) {
+	t.Helper()
+	store, err := storage.NewStorage(filepath.Join(t.TempDir(), "store.json"), storage.WithIngestController(bootResultController{result: ingest.BootResult{
+		PlaybackURL: "https://cdn.example/master.m3u8",
+		Renditions: []ingest.Rendition{
+			{Name: "1080p", ManifestURL: "https://cdn.example/1080p.m3u8"},
+			{Name: "720p", ManifestURL: "https://cdn.example/720p.m3u8"},
+			{Name: "480p", ManifestURL: "https://cdn.example/480p.m3u8"},
+		},
+	}}), storage.WithIngestRetries(1, 0))
+	if err != nil {
+		t.Fatalf("NewStorage: %v", err)
+	}
+	handler := NewHandler(store, auth.NewSessionManager(24*time.Hour))
+	handler.CookieSigningKey = []byte("0123456789abcdef0123456789abcdef")
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Ladder", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	if _, err := store.StartStream(channel.ID, []string{"1080p", "720p", "480p"}); err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	return handler, store, channel
+}
+
+func getChannelPlayback(t *testing.T, handler *Handler, channelID string, user *models.User, cookies ...*http.Cookie) (*httptest.ResponseRecorder, channelPlaybackResponse) {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/channels/"+channelID+"/playback", nil)
+	if user != nil {
+		req = withUser(req, *user)
+	}
+	for _, cookie := range cookies {
+		req.AddCookie(cookie)
+	}
+	rec := httptest.NewRecorder()
+	handler.ChannelByID(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("playback: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var payload channelPlaybackResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode playback: %v", err)
+	}
+	return rec, payload
+}
+
+func TestUserPlaybackPreferencesValidation(t *testing.T) {
+	handler, store := newTestHandler(t)
+	user, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	send := func(method, body string, authenticated bool) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, "/api/users/me/playback-preferences", strings.NewReader(body))
+		if authenticated {
+			req = withUser(req, user)
+		}
+		rec := httptest.NewRecorder()
+		handler.UserByID(rec, req)
+		return rec
+	}
+
+	if rec := send(http.MethodGet, "", false); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a session, got %d", rec.Code)
+	}
+	rec := send(http.MethodGet, "", true)
+	var prefs playbackPreferencesResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &prefs); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("expected defaults, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if prefs.DefaultQuality != models.PlaybackQualityAuto || prefs.PreferLowLatency || prefs.MutedAutoplay {
+		t.Fatalf("expected auto quality by default, got %+v", prefs)
+	}
+
+	for _, body := range []string{`{"defaultQuality":"4k"}`, `{"defaultQuality":""}`, `{"defaultQuality":"1080p"}`} {
+		rec := send(http.MethodPatch, body, true)
+		if rec.Code != http.StatusBadRequest || decodeAPIError(t, rec.Body.Bytes()).Error.Code != "validation_failed" {
+			t.Fatalf("%s: expected 400 validation_failed, got %d: %s", body, rec.Code, rec.Body.String())
+		}
+	}
+	if rec := send(http.MethodPatch, `{"quality":"low"}`, true); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected unknown fields rejected, got %d", rec.Code)
+	}
+
+	rec = send(http.MethodPatch, `{"defaultQuality":" High ","mutedAutoplay":true}`, true)
+	if err := json.Unmarshal(rec.Body.Bytes(), &prefs); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if prefs.DefaultQuality != models.PlaybackQualityHigh || !prefs.MutedAutoplay || prefs.UpdatedAt == nil {
+		t.Fatalf("expected normalized high quality with muted autoplay, got %+v", prefs)
+	}
+	rec = send(http.MethodPatch, `{"preferLowLatency":true}`, true)
+	if err := json.Unmarshal(rec.Body.Bytes(), &prefs); err != nil || prefs.DefaultQuality != models.PlaybackQualityHigh || !prefs.MutedAutoplay || !prefs.PreferLowLatency {
+		t.Fatalf("expected a partial update to keep other fields, got %s", rec.Body.String())
+	}
+}
+
+func TestChannelPlaybackIncludesPreferences(t *testing.T) {
+	handler, store, channel := newPlaybackPreferencesFixture(t)
+	viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	_, payload := getChannelPlayback(t, handler, channel.ID, &viewer)
+	if payload.PlaybackPreferences.DefaultQuality != models.PlaybackQualityAuto || payload.Playback == nil || payload.Playback.PreferredRendition != "" {
+		t.Fatalf("expected auto quality without a preferred rendition, got %+v", payload)
+	}
+	if len(payload.Playback.Renditions) != 3 {
+		t.Fatalf("expected the session's renditions alongside the preference, got %+v", payload.Playback.Renditions)
+	}
+
+	for quality, want := range map[string]string{"source": "1080p", "high": "1080p", "medium": "720p", "low": "480p"} {
+		quality := quality
+		latency := true
+		if _, err := store.UpdatePlaybackPreferences(viewer.ID, storage.PlaybackPreferencesUpdate{DefaultQuality: &quality, PreferLowLatency: &latency}); err != nil {
+			t.Fatalf("UpdatePlaybackPreferences: %v", err)
+		}
+		_, payload := getChannelPlayback(t, handler, channel.ID, &viewer)
+		if payload.PlaybackPreferences.DefaultQuality != quality || !payload.PlaybackPreferences.PreferLowLatency {
+			t.Fatalf("%s: expected the stored preferences in the payload, got %+v", quality, payload.PlaybackPreferences)
+		}
+		if payload.Playback.PreferredRendition != want {
+			t.Fatalf("%s: expected preferred rendition %s, got %q", quality, want, payload.Playback.PreferredRendition)
+		}
+	}
+}
+
+func TestAnonymousPlaybackPreferencesCookie(t *testing.T) {
+	handler, _, channel := newPlaybackPreferencesFixture(t)
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/playback-preferences", strings.NewReader(`{"defaultQuality":"low","mutedAutoplay":true}`))
+	rec := httptest.NewRecorder()
+	handler.PlaybackPreferences(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	cookie := findCookie(t, rec.Result().Cookies(), playbackPreferencesCookie)
+	if !cookie.HttpOnly || cookie.MaxAge <= 0 {
+		t.Fatalf("expected a persistent HttpOnly cookie, got %+v", cookie)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/playback-preferences", nil)
+	req.AddCookie(cookie)
+	rec = httptest.NewRecorder()
+	handler.PlaybackPreferences(rec, req)
+	var prefs playbackPreferencesResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &prefs); err != nil || prefs.DefaultQuality != models.PlaybackQualityLow || !prefs.MutedAutoplay {
+		t.Fatalf("expected the cookie to round-trip, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	_, payload := getChannelPlayback(t, handler, channel.ID, nil, cookie)
+	if payload.PlaybackPreferences.DefaultQuality != models.PlaybackQualityLow || payload.Playback.PreferredRendition != "480p" {
+		t.Fatalf("expected the cookie honoured on the watch page, got %+v", payload)
+	}
+
+	payloadPart, signature, _ := strings.Cut(cookie.Value, ".")
+	forged, err := handler.encodePlaybackPreferencesCookie(models.PlaybackPreferences{DefaultQuality: models.PlaybackQualitySource})
+	if err != nil {
+		t.Fatalf("encode cookie: %v", err)
+	}
+	forgedPayload, _, _ := strings.Cut(forged, ".")
+	for name, value := range map[string]string{
+		"swapped payload": forgedPayload + "." + signature,
+		"unsigned":        payloadPart,
+		"bad signature":   payloadPart + ".AAAA",
+	} {
+		_, payload := getChannelPlayback(t, handler, channel.ID, nil, &http.Cookie{Name: playbackPreferencesCookie, Value: value})
+		if payload.PlaybackPreferences.DefaultQuality != models.PlaybackQualityAuto || payload.Playback.PreferredRendition != "" {
+			t.Fatalf("%s: expected a tampered cookie ignored, got %+v", name, payload.PlaybackPreferences)
+		}
+	}
+
+	req = httptest.NewRequest(http.MethodPatch, "/api/playback-preferences", strings.NewReader(`{"defaultQuality":"ultra"}`))
+	rec = httptest.NewRecorder()
+	handler.PlaybackPreferences(rec, req)
+	if rec.Code != http.StatusBadRequest || len(rec.Result().Cookies()) != 0 {
+		t.Fatalf("expected 400 without a cookie for an invalid quality, got %d", rec.Code)
+	}
+
+	handler.CookieSigningKey = nil
+	req = httptest.NewRequest(http.MethodPatch, "/api/playback-preferences", strings.NewReader(`{"defaultQuality":"low"}`))
+	rec = httptest.NewRecorder()
+	handler.PlaybackPreferences(rec, req)
+	if rec.Code != http.StatusNotImplemented || decodeAPIError(t, rec.Body.Bytes()).Error.Code != "secret_key_unconfigured" {
+		t.Fatalf("expected 501 secret_key_unconfigured without a key, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestChannelPlaybackPreferencesStayPrivate(t *testing.T) {
+	handler, store, channel := newPlaybackPreferencesFixture(t)
+	handler.ReadCache = cache.New(cache.Config{})
+	source, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Source", Email: "source@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	saver, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Saver", Email: "saver@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	for user, quality := range map[string]string{source.ID: models.PlaybackQualitySource, saver.ID: models.PlaybackQualityLow} {
+		quality := quality
+		if _, err := store.UpdatePlaybackPreferences(user, storage.PlaybackPreferencesUpdate{DefaultQuality: &quality}); err != nil {
+			t.Fatalf("UpdatePlaybackPreferences: %v", err)
+		}
+	}
+
+	sourceRec, sourcePayload := getChannelPlayback(t, handler, channel.ID, &source)
+	saverRec, saverPayload := getChannelPlayback(t, handler, channel.ID, &saver)
+	if sourcePayload.Playback.PreferredRendition != "1080p" || saverPayload.Playback.PreferredRendition != "480p" {
+		t.Fatalf("expected each viewer's own rendition, got %q and %q", sourcePayload.Playback.PreferredRendition, saverPayload.Playback.PreferredRendition)
+	}
+	for _, rec := range []*httptest.ResponseRecorder{sourceRec, saverRec} {
+		if rec.Header().Get("Cache-Control") != "private, no-cache" || !strings.Contains(rec.Header().Get("Vary"), "Cookie") {
+			t.Fatalf("expected a private response varying by credentials, got %v", rec.Header())
+		}
+	}
+	if sourceRec.Header().Get("ETag") == saverRec.Header().Get("ETag") {
+		t.Fatalf("expected distinct ETags for distinct preferences")
+	}
+
+	req := withUser(httptest.NewRequest(http.MethodGet, "/api/channels/"+channel.ID+"/playback", nil), saver)
+	req.Header.Set("If-None-Match", sourceRec.Header().Get("ETag"))
+	rec := httptest.NewRecorder()
+	handler.ChannelByID(rec, req)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), `"preferredRendition":"480p"`) {
+		t.Fatalf("expected another viewer's ETag not to validate, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestPreferredRendition(t *testing.T) {
+	ladder := func(names ...string) []renditionManifestResponse {
+		renditions := make([]renditionManifestResponse, 0, len(names))
+		for _, name := range names {
+			renditions = append(renditions, renditionManifestResponse{Name: name})
+		}
+		return renditions
+	}
+	cases := []struct {
+		quality    string
+		renditions []renditionManifestResponse
+		want       string
+	}{
+		{models.PlaybackQualityAuto, ladder("1080p", "720p"), ""},
+		{models.PlaybackQualitySource, ladder("720p", "source", "1080p"), "source"},
+		{models.PlaybackQualitySource, ladder("720p", "1080p60"), "1080p60"},
+		{models.PlaybackQualityHigh, ladder("1440p", "1080p", "720p"), "1080p"},
+		{models.PlaybackQualityMedium, ladder("1080p", "900p", "480p"), "480p"},
+		{models.PlaybackQualityLow, ladder("1080p", "720p"), "720p"},
+		{models.PlaybackQualityMedium, ladder("720x1280", "1080x1920"), "720x1280"},
+		{models.PlaybackQualityLow, ladder("audio"), ""},
+		{models.PlaybackQualityHigh, nil, ""},
+	}
+	for _, tc := range cases {
+		if got := preferredRendition(tc.quality, tc.renditions); got != tc.want {
+			t.Fatalf("%s over %v: expected %q, got %q", tc.quality, tc.renditions, tc.want, got)
+		}
+	}
+}
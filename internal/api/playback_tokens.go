@@ -0,0 +1,299 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/auth"
+	"bitriver-live/internal/authz"
+	"bitriver-live/internal/models"
+)
+
+// restrictsPlayback reports whether a requested playback restriction limits
+// who may watch, as opposed to clearing it.
+func restrictsPlayback(value string) bool {
+	normalized := strings.ToLower(strings.TrimSpace(value))
+	return normalized != "" && normalized != "none"
+}
+
+// Reasons reported in playbackWithheldReason and as error codes when a viewer
+// may not watch a channel.
+const (
+	playbackWithheldAgeConfirmation = "age_confirmation_required"
+	playbackWithheldAgeRestricted   = "age_restricted"
+	playbackWithheldRestricted      = "playback_restricted"
+)
+
+// bypassesPlaybackGates reports whether the viewer may always watch the
+// channel: its owner, holders of channels.manage_any, and chat moderators.
+func bypassesPlaybackGates(viewer models.User, channel models.Channel) bool {
+	return viewer.ID == channel.OwnerID || canManageChannel(viewer, channel) || authz.Has(viewer, authz.ChatModerateAny)
+}
+
+// ageGate returns why a mature channel is withheld from the viewer, or an
+// empty string when they may watch. Anonymous viewers and users without a
+// confirmed birth date must confirm their age first.
+func ageGate(channel models.Channel, viewer *models.User, now time.Time) string {
+	if !channel.MatureContent {
+		return ""
+	}
+	if viewer == nil {
+		return playbackWithheldAgeConfirmation
+	}
+	if bypassesPlaybackGates(*viewer, channel) {
+		return ""
+	}
+	age, confirmed := viewer.AgeAt(now)
+	if !confirmed {
+		return playbackWithheldAgeConfirmation
+	}
+	if age < models.MatureContentMinimumAge {
+		return playbackWithheldAgeRestricted
+	}
+	return ""
+}
+
+// ageGateError is the error returned by endpoints that serve a single
+// mature item, carrying the ageGate reason as its code.
+func ageGateError(reason string) RequestError {
+	message := "confirm your birth date to watch mature content"
+	if reason == playbackWithheldAgeRestricted {
+		message = fmt.Sprintf("mature content is limited to viewers aged %d or older", models.MatureContentMinimumAge)
+	}
+	return RequestError{Status: http.StatusForbidden, CodeVal: reason, Message: message}
+}
+
+// playbackSubject decides whom a playback token is issued to. Everyone may
+// watch an unrestricted channel. On a restricted one, owners, channel
+// managers, and moderators always qualify; followers and subscribers qualify
+// per the restriction, with subscribers also satisfying a followers-only
+// channel. Everyone else gets an anonymous token only when the channel allows
+// previews.
+func playbackSubject(channel models.Channel, viewer *models.User, following, subscribed bool) (string, bool) {
+	if channel.PlaybackRestriction == "" {
+		if viewer != nil {
+			return viewer.ID, true
+		}
+		return auth.AnonymousPlaybackSubject, true
+	}
+	if viewer != nil {
+		qualifies := bypassesPlaybackGates(*viewer, channel)
+		switch channel.PlaybackRestriction {
+		case models.PlaybackRestrictionFollowers:
+			qualifies = qualifies || following || subscribed
+		case models.PlaybackRestrictionSubscribers:
+			qualifies = qualifies || subscribed
+		}
+		if qualifies {
+			return viewer.ID, true
+		}
+	}
+	if channel.PlaybackPreviews {
+		return auth.AnonymousPlaybackSubject, true
+	}
+	return "", false
+}
+
+// signPlayback rewrites playback's URLs to carry a token for the viewer. It
+// returns false when the viewer may not watch the restricted channel or no
+// signer is configured, in which case playback must be withheld.
+func (h *Handler) signPlayback(channel models.Channel, viewer *models.User, following, subscribed bool, playback *playbackStreamResponse) bool {
+	if h.PlaybackSigner == nil {
+		if logger := h.logger(); logger != nil {
+			logger.Warn("withholding restricted playback: no playback signing key configured", "channel_id", channel.ID)
+		}
+		return false
+	}
+	subject, ok := playbackSubject(channel, viewer, following, subscribed)
+	if !ok {
+		return false
+	}
+	token, expires, err := h.PlaybackSigner.Issue(channel.ID, subject)
+	if err != nil {
+		if logger := h.logger(); logger != nil {
+			logger.Error("issue playback token", "channel_id", channel.ID, "error", err)
+		}
+		return false
+	}
+	playback.PlaybackURL = withPlaybackToken(playback.PlaybackURL, token)
+	playback.OriginURL = withPlaybackToken(playback.OriginURL, token)
+	for i := range playback.Renditions {
+		playback.Renditions[i].ManifestURL = withPlaybackToken(playback.Renditions[i].ManifestURL, token)
+	}
+	for i := range playback.Endpoints {
+		playback.Endpoints[i].URL = withPlaybackToken(playback.Endpoints[i].URL, token)
+	}
+	expiresAt := expires.Format(time.RFC3339)
+	playback.TokenExpiresAt = &expiresAt
+	return true
+}
+
+// playbackTokenPathPrefix starts the path of signed HLS URLs, which carry the
+// token as the next path segment: /_token/{token}/live/{job}/master.m3u8.
+// Players resolve the relative URIs in each playlist against that path, so
+// child playlists and segments carry the token as well.
+const playbackTokenPathPrefix = "/_token/"
+
+// withPlaybackToken adds token to an HLS playlist URL as a path prefix. Other
+// URLs, such as WebRTC origins, take it as the token query parameter.
+func withPlaybackToken(raw, token string) string {
+	if strings.TrimSpace(raw) == "" {
+		return raw
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil {
+		return raw
+	}
+	if strings.HasSuffix(strings.ToLower(parsed.Path), ".m3u8") {
+		parsed.Path = playbackTokenPathPrefix + token + path.Clean("/"+parsed.Path)
+		parsed.RawPath = ""
+		return parsed.String()
+	}
+	query := parsed.Query()
+	query.Set("token", token)
+	parsed.RawQuery = query.Encode()
+	return parsed.String()
+}
+
+// splitPlaybackTokenPath separates the token from a signed request path,
+// returning the token and the path of the file it asks for.
+func splitPlaybackTokenPath(requestPath string) (string, string, bool) {
+	rest, ok := strings.CutPrefix(requestPath, playbackTokenPathPrefix)
+	if !ok {
+		return "", "", false
+	}
+	token, filePath, ok := strings.Cut(rest, "/")
+	if !ok || token == "" {
+		return "", "", false
+	}
+	return token, "/" + filePath, true
+}
+
+type playbackAuthorizationResponse struct {
+	ChannelID string `json:"channelId"`
+	Subject   string `json:"subject,omitempty"`
+	ExpiresAt string `json:"expiresAt,omitempty"`
+}
+
+// PlaybackAuthorize verifies a playback request for nginx auth_request and
+// CDNs. The X-Original-URI header names the request being authorized and is
+// required. A signed path carries the token after playbackTokenPathPrefix,
+// and the rest of the path must sit under one of the channel's live
+// manifests, so a token only unlocks its own channel's playlists and
+// segments. An unsigned path must sit under the live stream of an
+// unrestricted channel, so stripping the token from a restricted stream's
+// URL does not unlock it. It answers 200 with the channel, plus the token's
+// claims for signed paths, or 403.
+func (h *Handler) PlaybackAuthorize(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	w.Header().Set("Cache-Control", "no-store")
+
+	original := strings.TrimSpace(r.Header.Get("X-Original-URI"))
+	if original == "" {
+		WriteError(w, http.StatusForbidden, errors.New("X-Original-URI header required"))
+		return
+	}
+	parsed, err := url.Parse(original)
+	if err != nil {
+		WriteError(w, http.StatusForbidden, errors.New("invalid original uri"))
+		return
+	}
+	requestPath := path.Clean("/" + parsed.Path)
+	token, filePath, ok := splitPlaybackTokenPath(requestPath)
+	if !ok {
+		h.authorizeUnsignedPlayback(w, r, requestPath)
+		return
+	}
+	if h.PlaybackSigner == nil {
+		WriteRequestError(w, ServiceUnavailableError("signed playback is not configured"))
+		return
+	}
+
+	claims, err := h.PlaybackSigner.Verify(token)
+	if err != nil {
+		WriteError(w, http.StatusForbidden, err)
+		return
+	}
+	if channelID := strings.TrimSpace(r.URL.Query().Get("channel")); channelID != "" && channelID != claims.ChannelID {
+		WriteError(w, http.StatusForbidden, errors.New("playback token issued for another channel"))
+		return
+	}
+	if !h.playbackPathAllowed(claims.ChannelID, filePath) {
+		WriteError(w, http.StatusForbidden, errors.New("playback token does not cover this stream"))
+		return
+	}
+
+	w.Header().Set("X-Playback-Channel", claims.ChannelID)
+	w.Header().Set("X-Playback-Subject", claims.Subject)
+	WriteJSON(w, http.StatusOK, playbackAuthorizationResponse{
+		ChannelID: claims.ChannelID,
+		Subject:   claims.Subject,
+		ExpiresAt: claims.ExpiresAt.Format(time.RFC3339),
+	})
+}
+
+// authorizeUnsignedPlayback answers PlaybackAuthorize for a path without a
+// token. The path must lie under the live stream of a channel without a
+// playback restriction; restricted streams and paths outside every live
+// stream are refused.
+func (h *Handler) authorizeUnsignedPlayback(w http.ResponseWriter, r *http.Request, requestPath string) {
+	channels, err := h.Store.ListChannels("", "")
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, err)
+		return
+	}
+	for _, channel := range channels {
+		if channel.LiveState != "live" || !h.playbackPathAllowed(channel.ID, requestPath) {
+			continue
+		}
+		if channel.PlaybackRestriction != "" {
+			WriteError(w, http.StatusForbidden, errors.New("playback token required"))
+			return
+		}
+		if channelID := strings.TrimSpace(r.URL.Query().Get("channel")); channelID != "" && channelID != channel.ID {
+			WriteError(w, http.StatusForbidden, errors.New("stream belongs to another channel"))
+			return
+		}
+		w.Header().Set("X-Playback-Channel", channel.ID)
+		WriteJSON(w, http.StatusOK, playbackAuthorizationResponse{ChannelID: channel.ID})
+		return
+	}
+	WriteError(w, http.StatusForbidden, errors.New("playback token required"))
+}
+
+// playbackPathAllowed reports whether requestPath lies in the directory of
+// the channel's live master playlist or one of its rendition manifests, so a
+// token for one channel cannot unlock another channel's segments.
+func (h *Handler) playbackPathAllowed(channelID, requestPath string) bool {
+	session, live := h.Store.CurrentStreamSession(channelID)
+	if !live {
+		return false
+	}
+	manifests := []string{session.PlaybackURL}
+	for _, manifest := range session.RenditionManifests {
+		manifests = append(manifests, manifest.ManifestURL)
+	}
+	requestPath = path.Clean("/" + requestPath)
+	for _, manifest := range manifests {
+		parsed, err := url.Parse(strings.TrimSpace(manifest))
+		if err != nil || parsed.Path == "" {
+			continue
+		}
+		dir := path.Dir(path.Clean("/" + parsed.Path))
+		if dir == "/" {
+			continue
+		}
+		if strings.HasPrefix(requestPath, dir+"/") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,528 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/auth"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+// liveSessionRepository reports a fixed live session for every channel.
+type liveSessionRepository struct {
+	storage.Repository
+	session models.StreamSession
+}
+
+func (r liveSessionRepository) CurrentStreamSession(channelID string) (models.StreamSession, bool) {
+	session := r.session
+	session.ChannelID = channelID
+	return session, true
+}
+
+type restrictedPlaybackFixture struct {
+	handler    *Handler
+	store      *storage.Storage
+	signer     *auth.PlaybackSigner
+	channel    models.Channel
+	owner      models.User
+	follower   models.User
+	subscriber models.User
+	stranger   models.User
+}
+
+func newRestrictedPlaybackFixture(t *testing.T, now time.Time) restrictedPlaybackFixture {
+	t.Helper()
+	handler, store := newTestHandler(t)
+	signer, err := auth.NewPlaybackSigner(auth.PlaybackSignerConfig{Key: "playback-secret", TTL: time.Minute, Now: func() time.Time { return now }})
+	if err != nil {
+		t.Fatalf("NewPlaybackSigner: %v", err)
+	}
+	handler.PlaybackSigner = signer
+	handler.Store = liveSessionRepository{
+		Repository: store,
+		session: models.StreamSession{
+			ID:          "session-1",
+			StartedAt:   now,
+			PlaybackURL: "https://cdn.example.com/live/job-1/master.m3u8",
+			RenditionManifests: []models.RenditionManifest{
+				{Name: "720p", ManifestURL: "https://cdn.example.com/live/job-1/720p/index.m3u8", Bitrate: 3000},
+			},
+		},
+	}
+
+	f := restrictedPlaybackFixture{handler: handler, store: store, signer: signer}
+	for _, spec := range []struct {
+		user  *models.User
+		email string
+		roles []string
+	}{
+		{&f.owner, "owner@example.com", []string{"creator"}},
+		{&f.follower, "follower@example.com", nil},
+		{&f.subscriber, "subscriber@example.com", nil},
+		{&f.stranger, "stranger@example.com", nil},
+	} {
+		user, err := store.CreateUser(storage.CreateUserParams{DisplayName: spec.email, Email: spec.email, Roles: spec.roles})
+		if err != nil {
+			t.Fatalf("CreateUser %s: %v", spec.email, err)
+		}
+		*spec.user = user
+	}
+	f.channel, err = store.CreateChannel(f.owner.ID, "Members only", "music", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	live := "live"
+	if f.channel, err = store.UpdateChannel(f.channel.ID, storage.ChannelUpdate{LiveState: &live}); err != nil {
+		t.Fatalf("UpdateChannel live: %v", err)
+	}
+	if err := store.FollowChannel(f.follower.ID, f.channel.ID); err != nil {
+		t.Fatalf("FollowChannel: %v", err)
+	}
+	if _, err := store.CreateSubscription(storage.CreateSubscriptionParams{
+		ChannelID: f.channel.ID,
+		UserID:    f.subscriber.ID,
+		Tier:      "Tier 1",
+		Provider:  "internal",
+		Amount:    models.MustParseMoney("5"),
+		Currency:  "USD",
+		Duration:  30 * 24 * time.Hour,
+	}); err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+	return f
+}
+
+func (f restrictedPlaybackFixture) restrict(t *testing.T, restriction string, previews bool) {
+	t.Helper()
+	if _, err := f.store.UpdateChannel(f.channel.ID, storage.ChannelUpdate{PlaybackRestriction: &restriction, PlaybackPreviews: &previews}); err != nil {
+		t.Fatalf("UpdateChannel restriction: %v", err)
+	}
+}
+
+func (f restrictedPlaybackFixture) playback(t *testing.T, viewer *models.User) channelPlaybackResponse {
+	t.Helper()
+	req := httptest.NewRequest(http.MethodGet, "/api/channels/"+f.channel.ID+"/playback", nil)
+	if viewer != nil {
+		req = withUser(req, *viewer)
+	}
+	rec := httptest.NewRecorder()
+	f.handler.ChannelByID(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected playback status 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var payload channelPlaybackResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode playback response: %v", err)
+	}
+	return payload
+}
+
+func playbackToken(t *testing.T, rawURL string) string {
+	t.Helper()
+	parsed, err := url.Parse(rawURL)
+	if err != nil {
+		t.Fatalf("parse playback url %q: %v", rawURL, err)
+	}
+	token, filePath, ok := splitPlaybackTokenPath(parsed.Path)
+	if !ok || !strings.HasPrefix(filePath, "/live/job-1/") {
+		t.Fatalf("expected a token path prefix on %s", rawURL)
+	}
+	return token
+}
+
+func TestChannelPlaybackIssuesTokensPerViewerState(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	f := newRestrictedPlaybackFixture(t, now)
+
+	for _, viewer := range []*models.User{&f.stranger, nil} {
+		public := f.playback(t, viewer)
+		if public.Playback == nil || public.Playback.PlaybackURL != "https://cdn.example.com/live/job-1/master.m3u8" || public.Playback.TokenExpiresAt != nil {
+			t.Fatalf("expected unsigned playback for a public channel, got %+v", public.Playback)
+		}
+	}
+
+	cases := []struct {
+		name        string
+		restriction string
+		previews    bool
+		viewer      *models.User
+		subject     string
+	}{
+		{name: "owner", restriction: models.PlaybackRestrictionSubscribers, viewer: &f.owner, subject: f.owner.ID},
+		{name: "subscriber", restriction: models.PlaybackRestrictionSubscribers, viewer: &f.subscriber, subject: f.subscriber.ID},
+		{name: "follower on subscriber channel", restriction: models.PlaybackRestrictionSubscribers, viewer: &f.follower},
+		{name: "follower on subscriber channel with previews", restriction: models.PlaybackRestrictionSubscribers, previews: true, viewer: &f.follower, subject: auth.AnonymousPlaybackSubject},
+		{name: "follower", restriction: models.PlaybackRestrictionFollowers, viewer: &f.follower, subject: f.follower.ID},
+		{name: "subscriber on follower channel", restriction: models.PlaybackRestrictionFollowers, viewer: &f.subscriber, subject: f.subscriber.ID},
+		{name: "stranger", restriction: models.PlaybackRestrictionFollowers, viewer: &f.stranger},
+		{name: "anonymous", restriction: models.PlaybackRestrictionFollowers},
+		{name: "anonymous with previews", restriction: models.PlaybackRestrictionFollowers, previews: true, subject: auth.AnonymousPlaybackSubject},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f.restrict(t, tc.restriction, tc.previews)
+			payload := f.playback(t, tc.viewer)
+			if tc.subject == "" {
+				if payload.Playback != nil || !payload.PlaybackWithheld {
+					t.Fatalf("expected playback to be withheld, got %+v", payload.Playback)
+				}
+				return
+			}
+			if payload.Playback == nil || payload.PlaybackWithheld {
+				t.Fatal("expected signed playback")
+			}
+			if payload.Playback.TokenExpiresAt == nil || *payload.Playback.TokenExpiresAt != now.Add(time.Minute).Format(time.RFC3339) {
+				t.Fatalf("unexpected token expiry %v", payload.Playback.TokenExpiresAt)
+			}
+			urls := []string{payload.Playback.PlaybackURL}
+			for _, rendition := range payload.Playback.Renditions {
+				urls = append(urls, rendition.ManifestURL)
+			}
+			for _, rawURL := range urls {
+				claims, err := f.signer.Verify(playbackToken(t, rawURL))
+				if err != nil {
+					t.Fatalf("verify token in %s: %v", rawURL, err)
+				}
+				if claims.ChannelID != f.channel.ID || claims.Subject != tc.subject {
+					t.Fatalf("expected claims for %s/%s, got %+v", f.channel.ID, tc.subject, claims)
+				}
+			}
+		})
+	}
+}
+
+func TestWithPlaybackToken(t *testing.T) {
+	cases := []struct {
+		raw  string
+		want string
+	}{
+		{raw: "https://cdn.example.com/live/job-1/master.m3u8", want: "https://cdn.example.com/_token/tok.sig/live/job-1/master.m3u8"},
+		{raw: "https://cdn.example.com/live/job-1/720p/index.m3u8?v=2", want: "https://cdn.example.com/_token/tok.sig/live/job-1/720p/index.m3u8?v=2"},
+		{raw: "wss://origin.example.com:3334/app/stream", want: "wss://origin.example.com:3334/app/stream?token=tok.sig"},
+		{raw: "", want: ""},
+	}
+	for _, tc := range cases {
+		if got := withPlaybackToken(tc.raw, "tok.sig"); got != tc.want {
+			t.Fatalf("withPlaybackToken(%q) = %q, want %q", tc.raw, got, tc.want)
+		}
+	}
+}
+
+func TestChannelPlaybackWithheldWithoutSigner(t *testing.T) {
+	f := newRestrictedPlaybackFixture(t, time.Now())
+	f.restrict(t, models.PlaybackRestrictionFollowers, true)
+	f.handler.PlaybackSigner = nil
+
+	payload := f.playback(t, &f.follower)
+	if payload.Playback != nil || !payload.PlaybackWithheld {
+		t.Fatalf("expected playback to be withheld without a signer, got %+v", payload.Playback)
+	}
+
+	body, _ := json.Marshal(map[string]string{"playbackRestriction": "subscribers"})
+	req := httptest.NewRequest(http.MethodPatch, "/api/channels/"+f.channel.ID, bytes.NewReader(body))
+	req = withUser(req, f.owner)
+	rec := httptest.NewRecorder()
+	f.handler.ChannelByID(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 when enabling restriction without a signer, got %d", rec.Code)
+	}
+}
+
+func TestChannelPlaybackListsEndpoints(t *testing.T) {
+	f := newRestrictedPlaybackFixture(t, time.Now())
+	f.handler.PlaybackSigner = nil
+
+	legacy := f.playback(t, &f.stranger)
+	if legacy.Playback == nil || legacy.Playback.PlaybackURL != "https://cdn.example.com/live/job-1/master.m3u8" {
+		t.Fatalf("expected the legacy playback URL, got %+v", legacy.Playback)
+	}
+	if legacy.Playback.Endpoints != nil || legacy.Playback.Protocol != "ll-hls" || legacy.Playback.PlayerHint != "hls.js" {
+		t.Fatalf("expected a single-URL HLS payload for a session without endpoints, got %+v", legacy.Playback)
+	}
+
+	repo := f.handler.Store.(liveSessionRepository)
+	repo.session.PlaybackEndpoints = []models.PlaybackEndpoint{
+		{Protocol: models.PlaybackProtocolWebRTC, URL: "wss://cdn.example.com/live/job-1", Priority: 1},
+		{Protocol: models.PlaybackProtocolLLHLS, URL: "https://cdn.example.com/live/job-1/llhls.m3u8", Priority: 2},
+		{Protocol: models.PlaybackProtocolHLS, URL: "https://cdn.example.com/live/job-1/master.m3u8", Priority: 3},
+	}
+	repo.session.PlaybackURL = "https://cdn.example.com/live/job-1/llhls.m3u8"
+	f.handler.Store = repo
+
+	payload := f.playback(t, &f.stranger)
+	if payload.Playback == nil {
+		t.Fatal("expected playback")
+	}
+	if payload.Playback.PlaybackURL != "https://cdn.example.com/live/job-1/llhls.m3u8" {
+		t.Fatalf("expected the legacy URL to stay the preferred HLS endpoint, got %q", payload.Playback.PlaybackURL)
+	}
+	if payload.Playback.Protocol != "webrtc" || payload.Playback.PlayerHint != "ovenplayer" || payload.Playback.LatencyMode != models.LatencyModeUltraLow {
+		t.Fatalf("expected a WebRTC hint for a WebRTC-first session, got %+v", payload.Playback)
+	}
+	if len(payload.Playback.Endpoints) != 3 {
+		t.Fatalf("expected three endpoints, got %+v", payload.Playback.Endpoints)
+	}
+	for i, endpoint := range payload.Playback.Endpoints {
+		want := repo.session.PlaybackEndpoints[i]
+		if endpoint.Protocol != want.Protocol || endpoint.URL != want.URL || endpoint.Priority != want.Priority {
+			t.Fatalf("endpoint %d: expected %+v, got %+v", i, want, endpoint)
+		}
+	}
+
+	f.handler.PlaybackSigner = f.signer
+	f.restrict(t, models.PlaybackRestrictionFollowers, true)
+	signed := f.playback(t, &f.stranger)
+	if signed.Playback == nil || len(signed.Playback.Endpoints) != 3 {
+		t.Fatalf("expected signed endpoints, got %+v", signed.Playback)
+	}
+	for _, endpoint := range signed.Playback.Endpoints {
+		parsed, err := url.Parse(endpoint.URL)
+		if err != nil {
+			t.Fatalf("parse endpoint %q: %v", endpoint.URL, err)
+		}
+		token := parsed.Query().Get("token")
+		if endpoint.Protocol != models.PlaybackProtocolWebRTC {
+			token = playbackToken(t, endpoint.URL)
+		}
+		if _, err := f.signer.Verify(token); err != nil {
+			t.Fatalf("verify token in %s: %v", endpoint.URL, err)
+		}
+	}
+}
+
+func TestPlaybackAuthorize(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	f := newRestrictedPlaybackFixture(t, now)
+	f.restrict(t, models.PlaybackRestrictionFollowers, false)
+	token, _, err := f.signer.Issue(f.channel.ID, f.follower.ID)
+	if err != nil {
+		t.Fatalf("Issue: %v", err)
+	}
+	expiredSigner, err := auth.NewPlaybackSigner(auth.PlaybackSignerConfig{Key: "playback-secret", TTL: time.Minute, Now: func() time.Time { return now.Add(-time.Hour) }})
+	if err != nil {
+		t.Fatalf("NewPlaybackSigner: %v", err)
+	}
+	expired, _, err := expiredSigner.Issue(f.channel.ID, f.follower.ID)
+	if err != nil {
+		t.Fatalf("Issue expired: %v", err)
+	}
+
+	cases := []struct {
+		name     string
+		target   string
+		original string
+		status   int
+	}{
+		{name: "master playlist", target: "/api/playback/authorize", original: "/_token/" + token + "/live/job-1/master.m3u8", status: http.StatusOK},
+		{name: "rendition segment", target: "/api/playback/authorize", original: "/_token/" + token + "/live/job-1/720p/segment-3.ts", status: http.StatusOK},
+		{name: "matching channel", target: "/api/playback/authorize?channel=" + f.channel.ID, original: "/_token/" + token + "/live/job-1/master.m3u8", status: http.StatusOK},
+		{name: "other stream", target: "/api/playback/authorize", original: "/_token/" + token + "/live/job-2/master.m3u8", status: http.StatusForbidden},
+		{name: "escapes the stream", target: "/api/playback/authorize", original: "/_token/" + token + "/live/job-1/../job-2/master.m3u8", status: http.StatusForbidden},
+		{name: "other channel", target: "/api/playback/authorize?channel=other", original: "/_token/" + token + "/live/job-1/master.m3u8", status: http.StatusForbidden},
+		{name: "missing original uri", target: "/api/playback/authorize?token=" + token, status: http.StatusForbidden},
+		{name: "query token", target: "/api/playback/authorize", original: "/live/job-1/720p/segment-3.ts?token=" + token, status: http.StatusForbidden},
+		{name: "missing token", target: "/api/playback/authorize", original: "/live/job-1/master.m3u8", status: http.StatusForbidden},
+		{name: "tampered", target: "/api/playback/authorize", original: "/_token/x" + token + "/live/job-1/master.m3u8", status: http.StatusForbidden},
+		{name: "expired", target: "/api/playback/authorize", original: "/_token/" + expired + "/live/job-1/master.m3u8", status: http.StatusForbidden},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, tc.target, nil)
+			if tc.original != "" {
+				req.Header.Set("X-Original-URI", tc.original)
+			}
+			rec := httptest.NewRecorder()
+			f.handler.PlaybackAuthorize(rec, req)
+			if rec.Code != tc.status {
+				t.Fatalf("expected status %d, got %d: %s", tc.status, rec.Code, rec.Body.String())
+			}
+			if tc.status == http.StatusOK && rec.Header().Get("X-Playback-Subject") != f.follower.ID {
+				t.Fatalf("expected subject header %s, got %q", f.follower.ID, rec.Header().Get("X-Playback-Subject"))
+			}
+		})
+	}
+
+	// Expiry holds whatever the channel's current setting.
+	f.restrict(t, "", false)
+	rec := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/api/playback/authorize", nil)
+	req.Header.Set("X-Original-URI", "/_token/"+expired+"/live/job-1/720p/segment-4.ts")
+	f.handler.PlaybackAuthorize(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected an expired token to be refused once the restriction is lifted, got %d", rec.Code)
+	}
+
+	f.handler.PlaybackSigner = nil
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodGet, "/api/playback/authorize", nil)
+	req.Header.Set("X-Original-URI", "/_token/"+token+"/live/job-1/master.m3u8")
+	f.handler.PlaybackAuthorize(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503 without a signer, got %d", rec.Code)
+	}
+}
+
+func TestPlaybackAuthorizeUnsignedPaths(t *testing.T) {
+	f := newRestrictedPlaybackFixture(t, time.Now())
+	authorize := func(original string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/playback/authorize", nil)
+		req.Header.Set("X-Original-URI", original)
+		rec := httptest.NewRecorder()
+		f.handler.PlaybackAuthorize(rec, req)
+		return rec
+	}
+
+	f.restrict(t, models.PlaybackRestrictionSubscribers, true)
+	for _, original := range []string{"/live/job-1/master.m3u8", "/live/job-1/720p/segment-3.ts", "/_token/../live/job-1/master.m3u8"} {
+		if rec := authorize(original); rec.Code != http.StatusForbidden {
+			t.Fatalf("expected the bare path %s of a restricted stream to be refused, got %d", original, rec.Code)
+		}
+	}
+
+	f.restrict(t, "", false)
+	rec := authorize("/live/job-1/720p/segment-3.ts")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected an unrestricted stream to play unsigned, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("X-Playback-Channel"); got != f.channel.ID {
+		t.Fatalf("expected channel header %s, got %q", f.channel.ID, got)
+	}
+	if rec := authorize("/live/job-2/master.m3u8"); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected a path outside every live stream to be refused, got %d", rec.Code)
+	}
+
+	// Unsigned playback does not need a signing key.
+	f.handler.PlaybackSigner = nil
+	if rec := authorize("/live/job-1/master.m3u8"); rec.Code != http.StatusOK {
+		t.Fatalf("expected unsigned playback without a signer, got %d", rec.Code)
+	}
+}
+
+func TestChannelPlaybackGates(t *testing.T) {
+	f := newRestrictedPlaybackFixture(t, time.Now())
+	viewers := map[string]*models.User{"anonymous": nil, "unconfirmed": &f.stranger, "owner": &f.owner, "follower": &f.follower}
+	for _, spec := range []struct {
+		name  string
+		roles []string
+		born  time.Time
+	}{
+		{name: "minor", born: time.Now().AddDate(-models.MatureContentMinimumAge+1, 0, 0)},
+		{name: "adult", born: time.Date(1990, time.March, 3, 0, 0, 0, 0, time.UTC)},
+		{name: "moderator", roles: []string{"moderator"}},
+	} {
+		user, err := f.store.CreateUser(storage.CreateUserParams{DisplayName: spec.name, Email: spec.name + "@example.com", Roles: spec.roles})
+		if err != nil {
+			t.Fatalf("CreateUser %s: %v", spec.name, err)
+		}
+		if !spec.born.IsZero() {
+			if user, err = f.store.ConfirmUserBirthDate(user.ID, spec.born); err != nil {
+				t.Fatalf("ConfirmUserBirthDate %s: %v", spec.name, err)
+			}
+		}
+		viewers[spec.name] = &user
+	}
+
+	cases := []struct {
+		gate   string
+		reason map[string]string
+	}{
+		{gate: "mature", reason: map[string]string{
+			"anonymous":   playbackWithheldAgeConfirmation,
+			"unconfirmed": playbackWithheldAgeConfirmation,
+			"follower":    playbackWithheldAgeConfirmation,
+			"minor":       playbackWithheldAgeRestricted,
+		}},
+		{gate: "followers", reason: map[string]string{
+			"anonymous":   playbackWithheldRestricted,
+			"unconfirmed": playbackWithheldRestricted,
+			"minor":       playbackWithheldRestricted,
+			"adult":       playbackWithheldRestricted,
+		}},
+	}
+	for _, tc := range cases {
+		mature := tc.gate == "mature"
+		restriction := ""
+		if tc.gate == "followers" {
+			restriction = models.PlaybackRestrictionFollowers
+		}
+		if _, err := f.store.UpdateChannel(f.channel.ID, storage.ChannelUpdate{MatureContent: &mature, PlaybackRestriction: &restriction}); err != nil {
+			t.Fatalf("UpdateChannel %s: %v", tc.gate, err)
+		}
+		for name, viewer := range viewers {
+			t.Run(tc.gate+"/"+name, func(t *testing.T) {
+				payload := f.playback(t, viewer)
+				want := tc.reason[name]
+				if payload.PlaybackWithheldReason != want || payload.PlaybackWithheld != (want != "") {
+					t.Fatalf("expected withheld reason %q, got %q (withheld=%v)", want, payload.PlaybackWithheldReason, payload.PlaybackWithheld)
+				}
+				if want == "" && payload.Playback == nil {
+					t.Fatal("expected playback for an allowed viewer")
+				}
+				if payload.Channel.MatureContent != mature {
+					t.Fatalf("expected matureContent=%v in the channel payload", mature)
+				}
+			})
+		}
+	}
+}
+
+func TestMatureRecordingsRequireAgeConfirmation(t *testing.T) {
+	f := newRestrictedPlaybackFixture(t, time.Now())
+	f.handler.Store = f.store
+	if _, err := f.store.StartStream(f.channel.ID, []string{"720p"}); err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	if _, err := f.store.StopStream(f.channel.ID, 3); err != nil {
+		t.Fatalf("StopStream: %v", err)
+	}
+	recordings, err := f.store.ListRecordings(f.channel.ID, true)
+	if err != nil || len(recordings) != 1 {
+		t.Fatalf("expected one recording, got %d (%v)", len(recordings), err)
+	}
+	if _, err := f.store.PublishRecording(recordings[0].ID); err != nil {
+		t.Fatalf("PublishRecording: %v", err)
+	}
+	mature := true
+	if _, err := f.store.UpdateChannel(f.channel.ID, storage.ChannelUpdate{MatureContent: &mature}); err != nil {
+		t.Fatalf("UpdateChannel: %v", err)
+	}
+	adult, err := f.store.ConfirmUserBirthDate(f.follower.ID, time.Date(1985, time.July, 1, 0, 0, 0, 0, time.UTC))
+	if err != nil {
+		t.Fatalf("ConfirmUserBirthDate: %v", err)
+	}
+
+	for _, tc := range []struct {
+		name   string
+		viewer *models.User
+		status int
+		code   string
+	}{
+		{name: "anonymous", status: http.StatusForbidden, code: playbackWithheldAgeConfirmation},
+		{name: "unconfirmed", viewer: &f.stranger, status: http.StatusForbidden, code: playbackWithheldAgeConfirmation},
+		{name: "adult", viewer: &adult, status: http.StatusOK},
+		{name: "owner", viewer: &f.owner, status: http.StatusOK},
+	} {
+		t.Run(tc.name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodGet, "/api/recordings/"+recordings[0].ID, nil)
+			if tc.viewer != nil {
+				req = withUser(req, *tc.viewer)
+			}
+			rec := httptest.NewRecorder()
+			f.handler.RecordingByID(rec, req)
+			if rec.Code != tc.status {
+				t.Fatalf("expected status %d, got %d: %s", tc.status, rec.Code, rec.Body.String())
+			}
+			if tc.code != "" && !strings.Contains(rec.Body.String(), `"code":"`+tc.code+`"`) {
+				t.Fatalf("expected error code %s, got %s", tc.code, rec.Body.String())
+			}
+		})
+	}
+}
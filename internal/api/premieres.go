@@ -0,0 +1,75 @@
+package api
+
+import (
+	"time"
+
+	"bitriver-live/internal/models"
+)
+
+// recordingPremiereResponse describes a premiere in progress. Players seek
+// to OffsetSeconds, measured at ServerTime, so every viewer watches the same
+// moment regardless of when they joined or how far their clock is off.
+type recordingPremiereResponse struct {
+	StartsAt      string  `json:"startsAt"`
+	EndsAt        string  `json:"endsAt"`
+	OffsetSeconds float64 `json:"offsetSeconds"`
+	ServerTime    string  `json:"serverTime"`
+	// ChatChannelID is the channel whose live chat runs alongside the
+	// premiere.
+	ChatChannelID string `json:"chatChannelId"`
+}
+
+// directoryPremiereResponse marks a directory channel that is premiering a
+// recording rather than streaming live.
+type directoryPremiereResponse struct {
+	RecordingID string `json:"recordingId"`
+	Title       string `json:"title"`
+	StartsAt    string `json:"startsAt"`
+	EndsAt      string `json:"endsAt"`
+}
+
+// newRecordingPremiereResponse returns the premiere playing at now, or nil
+// once the recording behaves as an ordinary VOD.
+func newRecordingPremiereResponse(recording models.Recording, now time.Time) *recordingPremiereResponse {
+	if !recording.PremieringAt(now) {
+		return nil
+	}
+	start, end, _ := recording.PremiereWindow()
+	return &recordingPremiereResponse{
+		StartsAt:      start.Format(time.RFC3339Nano),
+		EndsAt:        end.Format(time.RFC3339Nano),
+		OffsetSeconds: premiereOffset(start, now),
+		ServerTime:    now.UTC().Format(time.RFC3339Nano),
+		ChatChannelID: recording.ChannelID,
+	}
+}
+
+// premiereOffset is how far into the recording a premiere that started at
+// start has played by now.
+func premiereOffset(start, now time.Time) float64 {
+	return now.Sub(start).Seconds()
+}
+
+// premieresByChannel maps each channel to the earliest premiere playing on
+// it at now. Directory listings treat a lookup failure as no premieres.
+func (h *Handler) premieresByChannel(now time.Time) map[string]*directoryPremiereResponse {
+	recordings, err := h.Store.ListPremieringRecordings(now)
+	if err != nil {
+		h.logger().Warn("failed to list premiering recordings", "error", err)
+		return nil
+	}
+	premieres := make(map[string]*directoryPremiereResponse, len(recordings))
+	for _, recording := range recordings {
+		if _, ok := premieres[recording.ChannelID]; ok {
+			continue
+		}
+		start, end, _ := recording.PremiereWindow()
+		premieres[recording.ChannelID] = &directoryPremiereResponse{
+			RecordingID: recording.ID,
+			Title:       recording.Title,
+			StartsAt:    start.Format(time.RFC3339Nano),
+			EndsAt:      end.Format(time.RFC3339Nano),
+		}
+	}
+	return premieres
+}
@@ -0,0 +1,130 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/authz"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+func TestRecordingPremiereOffsetIsSharedByEveryViewer(t *testing.T) {
+	start := time.Date(2026, 3, 1, 20, 0, 0, 0, time.UTC)
+	published := start
+	recording := models.Recording{ID: "rec", ChannelID: "chan", DurationSeconds: 600, PremiereAt: &start, PublishedAt: &published}
+
+	if premiere := newRecordingPremiereResponse(recording, start.Add(-time.Second)); premiere != nil {
+		t.Fatalf("expected no premiere before it starts, got %+v", premiere)
+	}
+	premiere := newRecordingPremiereResponse(recording, start.Add(90*time.Second+250*time.Millisecond))
+	if premiere == nil {
+		t.Fatal("expected the premiere to be playing")
+	}
+	if premiere.OffsetSeconds != 90.25 || premiere.ChatChannelID != "chan" {
+		t.Fatalf("expected a 90.25s offset in the channel's chat, got %+v", premiere)
+	}
+	if premiere.EndsAt != start.Add(10*time.Minute).Format(time.RFC3339Nano) {
+		t.Fatalf("expected the premiere to end after the recording's duration, got %s", premiere.EndsAt)
+	}
+	if premiere := newRecordingPremiereResponse(recording, start.Add(10*time.Minute)); premiere != nil {
+		t.Fatalf("expected the recording to be an ordinary VOD once the premiere ends, got %+v", premiere)
+	}
+	recording.PublishedAt = nil
+	if premiere := newRecordingPremiereResponse(recording, start.Add(time.Minute)); premiere != nil {
+		t.Fatalf("expected no premiere until the recording is published, got %+v", premiere)
+	}
+}
+
+func TestRecordingPremiereVisibilityAndScheduling(t *testing.T) {
+	h, store := newTestHandler(t)
+	owner, channel := newStatusChannel(t, store)
+	viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	editor, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Editor", Email: "editor@example.com", Roles: []string{authz.RoleEditor}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if _, err := store.GrantChannelEditor(channel.ID, editor.ID, owner.ID, nil); err != nil {
+		t.Fatalf("GrantChannelEditor: %v", err)
+	}
+	if _, err := store.StartStream(channel.ID, []string{"720p"}); err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	// A premiere plays for the recording's duration, so give it one.
+	time.Sleep(1100 * time.Millisecond)
+	if _, err := store.StopStream(channel.ID, 0); err != nil {
+		t.Fatalf("StopStream: %v", err)
+	}
+	recordings, err := store.ListRecordings(channel.ID, true)
+	if err != nil || len(recordings) != 1 {
+		t.Fatalf("expected one recording, got %d (err %v)", len(recordings), err)
+	}
+	recording := recordings[0]
+
+	request := func(method string, user models.User, body string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(method, "/api/recordings/"+recording.ID, strings.NewReader(body)), user)
+		rec := httptest.NewRecorder()
+		h.RecordingByID(rec, req)
+		return rec
+	}
+
+	premiereAt := time.Now().Add(time.Hour).UTC().Truncate(time.Second)
+	schedule := `{"premiereAt":"` + premiereAt.Format(time.RFC3339) + `"}`
+	if rec := request(http.MethodPatch, editor, schedule); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected an editor to be refused, got %d", rec.Code)
+	}
+	if rec := request(http.MethodPatch, owner, `{"premiereAt":"tomorrow"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected a malformed time to be rejected, got %d", rec.Code)
+	}
+	past := `{"premiereAt":"` + time.Now().Add(-time.Hour).Format(time.RFC3339) + `"}`
+	if rec := request(http.MethodPatch, owner, past); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected a past premiere to be rejected, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := request(http.MethodPatch, owner, schedule); rec.Code != http.StatusOK {
+		t.Fatalf("expected the owner to schedule the premiere, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if rec := request(http.MethodGet, viewer, ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected the recording hidden before its premiere, got %d", rec.Code)
+	}
+	if _, err := store.StartDuePremieres(premiereAt); err != nil {
+		t.Fatalf("StartDuePremieres: %v", err)
+	}
+
+	h.Now = func() time.Time { return premiereAt.Add(500 * time.Millisecond) }
+	rec := request(http.MethodGet, viewer, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected the premiere to be watchable, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var during recordingResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &during); err != nil {
+		t.Fatalf("decode recording: %v", err)
+	}
+	if during.Premiere == nil || during.Premiere.OffsetSeconds != 0.5 || during.Premiere.ChatChannelID != channel.ID {
+		t.Fatalf("expected a synchronized premiere payload, got %+v", during.Premiere)
+	}
+	entries := h.buildDirectoryResponse([]models.Channel{channel}).Channels
+	if len(entries) != 1 || entries[0].Premiere == nil || entries[0].Premiere.RecordingID != recording.ID {
+		t.Fatalf("expected the directory to show the channel premiering, got %+v", entries)
+	}
+
+	h.Now = func() time.Time { return premiereAt.Add(time.Duration(recording.DurationSeconds) * time.Second) }
+	rec = request(http.MethodGet, viewer, "")
+	var after recordingResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &after); err != nil || rec.Code != http.StatusOK {
+		t.Fatalf("expected the recording to stay watchable, got %d (err %v)", rec.Code, err)
+	}
+	if after.Premiere != nil || after.PremiereAt == nil {
+		t.Fatalf("expected an ordinary VOD that remembers its premiere, got %+v", after)
+	}
+	if entries := h.buildDirectoryResponse([]models.Channel{channel}).Channels; entries[0].Premiere != nil {
+		t.Fatalf("expected the directory to drop the finished premiere, got %+v", entries[0].Premiere)
+	}
+}
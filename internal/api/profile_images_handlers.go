@@ -0,0 +1,93 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+
+	"bitriver-live/internal/storage"
+)
+
+// profileImageFormOverhead leaves room for multipart boundaries and headers
+// on top of the image itself.
+const profileImageFormOverhead = 64 << 10
+
+// profileImage serves POST /api/users/me/avatar and /api/users/me/banner.
+// The image arrives as the "file" field of a multipart form, is stored in
+// object storage, and replaces the signed-in user's current avatar or
+// banner.
+func (h *Handler) profileImage(w http.ResponseWriter, r *http.Request, kind storage.ProfileImageKind) {
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+	user, ok := h.requireAuthenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	limits, _ := storage.ProfileImageLimitsFor(kind)
+	tooLarge := RequestError{Status: http.StatusRequestEntityTooLarge, CodeVal: "image_too_large", Message: fmt.Sprintf("%s must not exceed %d bytes", kind, limits.MaxBytes)}
+	r.Body = http.MaxBytesReader(w, r.Body, int64(limits.MaxBytes)+profileImageFormOverhead)
+
+	reader, err := r.MultipartReader()
+	if err != nil {
+		WriteRequestError(w, ValidationError("expected a multipart form with a file field"))
+		return
+	}
+	var data []byte
+	for {
+		part, err := reader.NextPart()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				WriteRequestError(w, tooLarge)
+				return
+			}
+			WriteError(w, http.StatusBadRequest, fmt.Errorf("read multipart data: %w", err))
+			return
+		}
+		if part.FormName() != "file" || data != nil {
+			_ = part.Close()
+			continue
+		}
+		data, err = io.ReadAll(io.LimitReader(part, int64(limits.MaxBytes)+1))
+		_ = part.Close()
+		if err != nil {
+			var maxErr *http.MaxBytesError
+			if errors.As(err, &maxErr) {
+				WriteRequestError(w, tooLarge)
+				return
+			}
+			WriteError(w, http.StatusBadRequest, fmt.Errorf("read image: %w", err))
+			return
+		}
+		if len(data) > limits.MaxBytes {
+			WriteRequestError(w, tooLarge)
+			return
+		}
+	}
+	if len(data) == 0 {
+		WriteRequestError(w, ValidationError("file is required"))
+		return
+	}
+
+	profile, err := h.Store.SetProfileImage(user.ID, kind, data)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrObjectStorageUnavailable):
+			WriteRequestError(w, RequestError{Status: http.StatusNotImplemented, CodeVal: "object_storage_unconfigured", Message: fmt.Sprintf("%s uploads need object storage; set the %s URL on the profile instead", kind, kind), Err: err})
+		case errors.Is(err, storage.ErrInvalidProfileImage):
+			WriteRequestError(w, RequestError{Status: http.StatusBadRequest, CodeVal: "invalid_image", Message: err.Error(), Err: err})
+		default:
+			WriteError(w, http.StatusBadGateway, fmt.Errorf("store %s: %w", kind, err))
+		}
+		return
+	}
+	h.invalidateChatAuthor(user.ID)
+	h.invalidateDirectoryCache(r.Context())
+	h.writeProfileView(w, user, profile)
+}
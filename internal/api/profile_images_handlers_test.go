@@ -0,0 +1,166 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"image"
+	"image/png"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/auth"
+	"bitriver-live/internal/storage"
+)
+
+// fakeObjectServer accepts S3 PUT and DELETE requests and records them.
+type fakeObjectServer struct {
+	mu       sync.Mutex
+	requests []string
+}
+
+func (f *fakeObjectServer) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	f.mu.Lock()
+	f.requests = append(f.requests, r.Method+" "+r.URL.Path)
+	f.mu.Unlock()
+	switch r.Method {
+	case http.MethodPut:
+		w.WriteHeader(http.StatusOK)
+	case http.MethodDelete:
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		w.WriteHeader(http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *fakeObjectServer) recorded() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.requests...)
+}
+
+func testPNG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height))); err != nil {
+		t.Fatalf("encode png: %v", err)
+	}
+	return buf.Bytes()
+}
+
+func profileImageRequest(t *testing.T, path string, data []byte) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	part, err := form.CreateFormFile("file", "image.png")
+	if err != nil {
+		t.Fatalf("create form file: %v", err)
+	}
+	if _, err := part.Write(data); err != nil {
+		t.Fatalf("write form file: %v", err)
+	}
+	if err := form.Close(); err != nil {
+		t.Fatalf("close form: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, path, &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	return req
+}
+
+func TestProfileImageUploadReplacesPreviousObject(t *testing.T) {
+	objects := &fakeObjectServer{}
+	server := httptest.NewServer(objects)
+	defer server.Close()
+	store, err := storage.NewStorage(filepath.Join(t.TempDir(), "store.json"), storage.WithObjectStorage(storage.ObjectStorageConfig{
+		Endpoint:       server.URL,
+		Bucket:         "media",
+		PublicEndpoint: "https://cdn.example.com",
+		MaxRetries:     1,
+	}))
+	if err != nil {
+		t.Fatalf("NewStorage: %v", err)
+	}
+	handler := NewHandler(store, auth.NewSessionManager(time.Hour))
+	user, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Ava", Email: "ava@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	upload := func(data []byte) (*httptest.ResponseRecorder, profileViewResponse) {
+		rec := httptest.NewRecorder()
+		handler.UserByID(rec, withUser(profileImageRequest(t, "/api/users/me/avatar", data), user))
+		var profile profileViewResponse
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &profile); err != nil {
+				t.Fatalf("decode profile: %v", err)
+			}
+		}
+		return rec, profile
+	}
+
+	rec, first := upload(testPNG(t, 128, 128))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	prefix := "https://cdn.example.com/profiles/" + user.ID + "/avatar-"
+	if !strings.HasPrefix(first.AvatarURL, prefix) || !strings.HasSuffix(first.AvatarURL, ".png") {
+		t.Fatalf("expected avatar under %s, got %s", prefix, first.AvatarURL)
+	}
+	firstKey := strings.TrimPrefix(first.AvatarURL, "https://cdn.example.com/")
+	if got := objects.recorded(); len(got) != 1 || got[0] != "PUT /media/"+firstKey {
+		t.Fatalf("expected the avatar stored at %s, got %v", firstKey, got)
+	}
+
+	rec, second := upload(testPNG(t, 256, 256))
+	if rec.Code != http.StatusOK || second.AvatarURL == first.AvatarURL {
+		t.Fatalf("expected a new avatar, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := objects.recorded(); len(got) != 3 || got[2] != "DELETE /media/"+firstKey {
+		t.Fatalf("expected the previous avatar deleted, got %v", got)
+	}
+
+	rec, _ = upload([]byte("GIF89a not allowed"))
+	if rec.Code != http.StatusBadRequest || decodeAPIError(t, rec.Body.Bytes()).Error.Code != "invalid_image" {
+		t.Fatalf("expected 400 invalid_image, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec, _ = upload(make([]byte, 2<<20+1))
+	if rec.Code != http.StatusRequestEntityTooLarge || decodeAPIError(t, rec.Body.Bytes()).Error.Code != "image_too_large" {
+		t.Fatalf("expected 413 image_too_large, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if profile, _ := store.GetProfile(user.ID); profile.AvatarURL != second.AvatarURL {
+		t.Fatalf("expected rejected uploads to keep the avatar, got %s", profile.AvatarURL)
+	}
+}
+
+func TestProfileImageUploadWithoutObjectStorage(t *testing.T) {
+	handler, store := newTestHandler(t)
+	user, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Ava", Email: "ava@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.UserByID(rec, withUser(profileImageRequest(t, "/api/users/me/banner", testPNG(t, 1200, 300)), user))
+	if rec.Code != http.StatusNotImplemented || decodeAPIError(t, rec.Body.Bytes()).Error.Code != "object_storage_unconfigured" {
+		t.Fatalf("expected 501 object_storage_unconfigured, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodPut, "/api/profiles/"+user.ID, strings.NewReader(`{"bannerUrl":"https://images.example.com/banner.png"}`))
+	handler.ProfileByID(rec, withUser(req, user))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected URL-based banner to be accepted, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	req = httptest.NewRequest(http.MethodPut, "/api/profiles/"+user.ID, strings.NewReader(`{"avatarUrl":"javascript:alert(1)"}`))
+	handler.ProfileByID(rec, withUser(req, user))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected javascript: avatar to be rejected, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
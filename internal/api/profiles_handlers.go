@@ -6,6 +6,7 @@ import (
 	"strings"
 	"time"
 
+	"bitriver-live/internal/authz"
 	"bitriver-live/internal/models"
 	"bitriver-live/internal/storage"
 )
@@ -34,9 +35,33 @@ type upsertProfileRequest struct {
 }
 
 type cryptoAddressResponse struct {
-	Currency string `json:"currency"`
-	Address  string `json:"address"`
-	Note     string `json:"note,omitempty"`
+	Currency   string  `json:"currency"`
+	Address    string  `json:"address"`
+	Note       string  `json:"note,omitempty"`
+	Verified   bool    `json:"verified"`
+	VerifiedAt *string `json:"verifiedAt,omitempty"`
+}
+
+func newCryptoAddressResponse(addr models.CryptoAddress) cryptoAddressResponse {
+	response := cryptoAddressResponse{
+		Currency: string(addr.Currency),
+		Address:  addr.Address,
+		Note:     addr.Note,
+		Verified: addr.VerifiedAt != nil,
+	}
+	if addr.VerifiedAt != nil {
+		verifiedAt := addr.VerifiedAt.Format(time.RFC3339Nano)
+		response.VerifiedAt = &verifiedAt
+	}
+	return response
+}
+
+func newCryptoAddressResponses(addresses []models.CryptoAddress) []cryptoAddressResponse {
+	response := make([]cryptoAddressResponse, 0, len(addresses))
+	for _, addr := range addresses {
+		response = append(response, newCryptoAddressResponse(addr))
+	}
+	return response
 }
 
 type friendSummaryResponse struct {
@@ -53,6 +78,8 @@ type socialLinkResponse struct {
 type profileViewResponse struct {
 	UserID            string                  `json:"userId"`
 	DisplayName       string                  `json:"displayName"`
+	Username          string                  `json:"username"`
+	Badges            []userBadgeResponse     `json:"badges"`
 	Bio               string                  `json:"bio"`
 	AvatarURL         string                  `json:"avatarUrl"`
 	BannerURL         string                  `json:"bannerUrl"`
@@ -69,14 +96,32 @@ type profileViewResponse struct {
 func (h *Handler) Profiles(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
-		profiles := h.Store.ListProfiles()
+		query, paginated, err := parseUserListQuery(r.URL.Query(), false)
+		if err != nil {
+			WriteRequestError(w, err)
+			return
+		}
+		if paginated {
+			h.listProfilesPage(w, r, query)
+			return
+		}
+		profiles, err := h.Store.ListProfiles()
+		if err != nil {
+			writeStorageError(w, http.StatusInternalServerError, err)
+			return
+		}
 		response := make([]profileViewResponse, 0, len(profiles))
 		for _, profile := range profiles {
 			user, ok := h.Store.GetUser(profile.UserID)
 			if !ok {
 				continue
 			}
-			response = append(response, h.buildProfileViewResponse(user, profile))
+			view, err := h.buildProfileViewResponse(user, profile)
+			if err != nil {
+				writeStorageError(w, http.StatusInternalServerError, err)
+				return
+			}
+			response = append(response, view)
 		}
 		WriteJSON(w, http.StatusOK, response)
 	default:
@@ -84,6 +129,32 @@ func (h *Handler) Profiles(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// listProfilesPage serves the paginated profile listing. Only user managers
+// may search by email because the listing is public.
+func (h *Handler) listProfilesPage(w http.ResponseWriter, r *http.Request, query userListQuery) {
+	actor, ok := UserFromContext(r.Context())
+	query.Options.NameOnly = !ok || !authz.Has(actor, authz.UsersManage)
+	page, err := h.Store.ListProfilesPage(query.Options)
+	if err != nil {
+		writeStorageError(w, http.StatusInternalServerError, err)
+		return
+	}
+	items := make([]profileViewResponse, 0, len(page.Profiles))
+	for _, profile := range page.Profiles {
+		user, ok := h.Store.GetUser(profile.UserID)
+		if !ok {
+			continue
+		}
+		view, err := h.buildProfileViewResponse(user, profile)
+		if err != nil {
+			writeStorageError(w, http.StatusInternalServerError, err)
+			return
+		}
+		items = append(items, view)
+	}
+	writePage(w, query, items, page.Total)
+}
+
 func (h *Handler) ProfileByID(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/api/profiles/")
 	parts := strings.Split(path, "/")
@@ -101,7 +172,7 @@ func (h *Handler) ProfileByID(w http.ResponseWriter, r *http.Request) {
 		if !ok {
 			return
 		}
-		if actor.ID != userID && !actor.HasRole(roleAdmin) {
+		if actor.ID != userID && !authz.Has(actor, authz.UsersManage) {
 			WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
 			return
 		}
@@ -111,14 +182,19 @@ func (h *Handler) ProfileByID(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// handleGetProfile serves a profile by user ID or, failing that, by
+// username, so /api/profiles/@ada and /api/profiles/ada both resolve.
 func (h *Handler) handleGetProfile(userID string, w http.ResponseWriter, r *http.Request) {
 	user, ok := h.Store.GetUser(userID)
+	if !ok {
+		user, ok = h.findUserByHandle(userID)
+	}
 	if !ok {
 		WriteError(w, http.StatusNotFound, fmt.Errorf("user %s not found", userID))
 		return
 	}
-	profile, _ := h.Store.GetProfile(userID)
-	WriteJSON(w, http.StatusOK, h.buildProfileViewResponse(user, profile))
+	profile, _ := h.Store.GetProfile(user.ID)
+	h.writeProfileView(w, user, profile)
 }
 
 func (h *Handler) handleUpsertProfile(userID string, w http.ResponseWriter, r *http.Request) {
@@ -143,7 +219,7 @@ func (h *Handler) handleUpsertProfile(userID string, w http.ResponseWriter, r *h
 	if userUpdate.DisplayName != nil || userUpdate.Email != nil {
 		updatedUser, err := h.Store.UpdateUser(userID, userUpdate)
 		if err != nil {
-			WriteError(w, http.StatusBadRequest, err)
+			writeStorageError(w, http.StatusBadRequest, err)
 			return
 		}
 		user = updatedUser
@@ -180,12 +256,12 @@ func (h *Handler) handleUpsertProfile(userID string, w http.ResponseWriter, r *h
 		addresses := make([]models.CryptoAddress, 0, len(*req.DonationAddresses))
 		for _, addr := range *req.DonationAddresses {
 			normalized, err := storage.NormalizeDonationAddress(models.CryptoAddress{
-				Currency: addr.Currency,
+				Currency: models.CryptoCurrency(addr.Currency),
 				Address:  addr.Address,
 				Note:     addr.Note,
 			})
 			if err != nil {
-				WriteError(w, http.StatusBadRequest, err)
+				writeStorageError(w, http.StatusBadRequest, err)
 				return
 			}
 			addresses = append(addresses, normalized)
@@ -195,15 +271,30 @@ func (h *Handler) handleUpsertProfile(userID string, w http.ResponseWriter, r *h
 
 	profile, err := h.Store.UpsertProfile(userID, update)
 	if err != nil {
-		WriteError(w, http.StatusBadRequest, err)
+		writeStorageError(w, http.StatusBadRequest, err)
 		return
 	}
+	h.invalidateChatAuthor(userID)
+	h.invalidateDirectoryCache(r.Context())
 
-	WriteJSON(w, http.StatusOK, h.buildProfileViewResponse(user, profile))
+	h.writeProfileView(w, user, profile)
 }
 
-func (h *Handler) buildProfileViewResponse(user models.User, profile models.Profile) profileViewResponse {
-	channels := h.Store.ListChannels(user.ID, "")
+// writeProfileView answers with the profile view of user.
+func (h *Handler) writeProfileView(w http.ResponseWriter, user models.User, profile models.Profile) {
+	response, err := h.buildProfileViewResponse(user, profile)
+	if err != nil {
+		writeStorageError(w, http.StatusInternalServerError, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
+
+func (h *Handler) buildProfileViewResponse(user models.User, profile models.Profile) (profileViewResponse, error) {
+	channels, err := h.Store.ListChannels(user.ID, "")
+	if err != nil {
+		return profileViewResponse{}, err
+	}
 	channelResponses := make([]channelPublicResponse, 0, len(channels))
 	liveResponses := make([]channelPublicResponse, 0)
 	for _, channel := range channels {
@@ -228,29 +319,27 @@ func (h *Handler) buildProfileViewResponse(user models.User, profile models.Prof
 		})
 	}
 
-	donations := make([]cryptoAddressResponse, 0, len(profile.DonationAddresses))
-	for _, addr := range profile.DonationAddresses {
-		donations = append(donations, cryptoAddressResponse{
-			Currency: addr.Currency,
-			Address:  addr.Address,
-			Note:     addr.Note,
-		})
-	}
-
 	socialLinks := make([]socialLinkResponse, 0, len(profile.SocialLinks))
 	for _, link := range profile.SocialLinks {
 		socialLinks = append(socialLinks, socialLinkResponse{Platform: link.Platform, URL: link.URL})
 	}
 
+	badges, err := h.userBadges(user.ID)
+	if err != nil {
+		return profileViewResponse{}, err
+	}
+
 	response := profileViewResponse{
 		UserID:            user.ID,
 		DisplayName:       user.DisplayName,
+		Username:          user.Username,
+		Badges:            badges,
 		Bio:               profile.Bio,
 		AvatarURL:         profile.AvatarURL,
 		BannerURL:         profile.BannerURL,
 		SocialLinks:       socialLinks,
 		TopFriends:        friends,
-		DonationAddresses: donations,
+		DonationAddresses: newCryptoAddressResponses(profile.DonationAddresses),
 		Channels:          channelResponses,
 		LiveChannels:      liveResponses,
 		CreatedAt:         profile.CreatedAt.Format(time.RFC3339Nano),
@@ -260,5 +349,5 @@ func (h *Handler) buildProfileViewResponse(user models.User, profile models.Prof
 		id := *profile.FeaturedChannelID
 		response.FeaturedChannelID = &id
 	}
-	return response
+	return response, nil
 }
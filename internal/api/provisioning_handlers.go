@@ -0,0 +1,222 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+// provisioningClient is recorded as the actor on audit events emitted by the
+// provisioning API, which is called by an identity provider rather than a
+// user.
+const provisioningClient = "provisioning"
+
+type provisionUserRequest struct {
+	DisplayName string   `json:"displayName"`
+	Email       string   `json:"email"`
+	Roles       []string `json:"roles"`
+}
+
+type updateProvisionedUserRequest struct {
+	DisplayName *string   `json:"displayName"`
+	Roles       *[]string `json:"roles"`
+	Active      *bool     `json:"active"`
+}
+
+// provisioningAuthorized reports whether the request carries the provisioning
+// token. Session and personal access tokens are never accepted here, and the
+// provisioning token is never accepted anywhere else.
+func (h *Handler) provisioningAuthorized(r *http.Request) bool {
+	token := strings.TrimSpace(h.ProvisioningToken)
+	if token == "" || r == nil {
+		return false
+	}
+	header := strings.TrimSpace(r.Header.Get("Authorization"))
+	parts := strings.SplitN(header, " ", 2)
+	if len(parts) != 2 || !strings.EqualFold(parts[0], "bearer") {
+		return false
+	}
+	return constantTimeEqual(token, strings.TrimSpace(parts[1]))
+}
+
+// requireProvisioning answers 404 when provisioning is not configured, so the
+// API does not advertise it, and 401 when the token is wrong.
+func (h *Handler) requireProvisioning(w http.ResponseWriter, r *http.Request) bool {
+	if strings.TrimSpace(h.ProvisioningToken) == "" {
+		WriteError(w, http.StatusNotFound, fmt.Errorf("provisioning is not enabled"))
+		return false
+	}
+	if !h.provisioningAuthorized(r) {
+		WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid provisioning token"))
+		return false
+	}
+	return true
+}
+
+func (h *Handler) auditProvisioning(action string, user models.User, fields ...any) {
+	fields = append([]any{"action", action, "client", provisioningClient, "target_user_id", user.ID}, fields...)
+	h.auditLogger().Info("audit", fields...)
+}
+
+// ProvisioningUsers lets an identity provider look users up by email and
+// create them. POST is idempotent: provisioning an email that already exists
+// updates that user's display name and roles, reactivates them, and answers
+// 200 instead of 201.
+func (h *Handler) ProvisioningUsers(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodPost)
+		return
+	}
+	if !h.requireProvisioning(w, r) {
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		values := r.URL.Query()
+		page, perPage, err := parsePageParams(values)
+		if err != nil {
+			WriteRequestError(w, err)
+			return
+		}
+		query := userListQuery{
+			Options: storage.UserListOptions{
+				Email:  strings.TrimSpace(values.Get("email")),
+				Limit:  perPage,
+				Offset: (page - 1) * perPage,
+			},
+			Page:    page,
+			PerPage: perPage,
+		}
+		result, err := h.Store.ListUsersPage(query.Options)
+		if err != nil {
+			writeStorageError(w, http.StatusInternalServerError, err)
+			return
+		}
+		items := make([]userResponse, 0, len(result.Users))
+		for _, user := range result.Users {
+			items = append(items, newUserResponse(user))
+		}
+		writePage(w, query, items, result.Total)
+	case http.MethodPost:
+		var req provisionUserRequest
+		if !DecodeAndValidate(w, r, &req) {
+			return
+		}
+		email := strings.TrimSpace(req.Email)
+		if email == "" {
+			WriteRequestError(w, ValidationError("email is required"))
+			return
+		}
+		existing, err := h.Store.ListUsersPage(storage.UserListOptions{Email: email, Limit: 1})
+		if err != nil {
+			writeStorageError(w, http.StatusInternalServerError, err)
+			return
+		}
+		if len(existing.Users) > 0 {
+			deactivated := false
+			update := storage.UserUpdate{Deactivated: &deactivated}
+			if strings.TrimSpace(req.DisplayName) != "" {
+				update.DisplayName = &req.DisplayName
+			}
+			if req.Roles != nil {
+				update.Roles = &req.Roles
+			}
+			user, err := h.Store.UpdateUser(existing.Users[0].ID, update)
+			if err != nil {
+				writeStorageError(w, http.StatusBadRequest, err)
+				return
+			}
+			h.invalidateChatAuthor(user.ID)
+			h.auditProvisioning("user.provision_update", user, "roles", user.Roles)
+			WriteJSON(w, http.StatusOK, newUserResponse(user))
+			return
+		}
+		roles := req.Roles
+		if len(roles) == 0 {
+			roles = []string{"viewer"}
+		}
+		user, err := h.Store.CreateUser(storage.CreateUserParams{
+			DisplayName: req.DisplayName,
+			Email:       email,
+			Roles:       roles,
+		})
+		if err != nil {
+			writeStorageError(w, http.StatusBadRequest, err)
+			return
+		}
+		h.auditProvisioning("user.provision_create", user, "roles", user.Roles)
+		WriteJSON(w, http.StatusCreated, newUserResponse(user))
+	}
+}
+
+// ProvisioningUserByID reads, updates, and deactivates a provisioned user.
+// DELETE deactivates rather than deletes, so the user's channels, chat, and
+// recordings survive; deactivating revokes every session the user holds.
+func (h *Handler) ProvisioningUserByID(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPatch && r.Method != http.MethodDelete {
+		WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodPatch, http.MethodDelete)
+		return
+	}
+	if !h.requireProvisioning(w, r) {
+		return
+	}
+	id := strings.Trim(strings.TrimPrefix(r.URL.Path, "/api/admin/provisioning/users/"), "/")
+	if id == "" || strings.Contains(id, "/") {
+		WriteError(w, http.StatusNotFound, fmt.Errorf("user id missing"))
+		return
+	}
+	user, ok := h.Store.GetUser(id)
+	if !ok {
+		WriteError(w, http.StatusNotFound, fmt.Errorf("user %s not found", id))
+		return
+	}
+
+	var update storage.UserUpdate
+	switch r.Method {
+	case http.MethodGet:
+		WriteJSON(w, http.StatusOK, newUserResponse(user))
+		return
+	case http.MethodPatch:
+		var req updateProvisionedUserRequest
+		if !DecodeAndValidate(w, r, &req) {
+			return
+		}
+		update = storage.UserUpdate{DisplayName: req.DisplayName, Roles: req.Roles}
+		if req.Active != nil {
+			deactivated := !*req.Active
+			update.Deactivated = &deactivated
+		}
+	case http.MethodDelete:
+		deactivated := true
+		update.Deactivated = &deactivated
+	}
+
+	updated, err := h.Store.UpdateUser(id, update)
+	if err != nil {
+		writeStorageError(w, http.StatusBadRequest, err)
+		return
+	}
+	h.invalidateChatAuthor(updated.ID)
+	if updated.Deactivated() {
+		if err := h.sessionManager().RevokeUser(updated.ID); err != nil {
+			h.logger().Warn("revoke sessions of deactivated user", "user_id", updated.ID, "error", err)
+		}
+	}
+	switch {
+	case r.Method == http.MethodDelete:
+		h.auditProvisioning("user.provision_deactivate", updated)
+		w.WriteHeader(http.StatusNoContent)
+		return
+	case user.Deactivated() && !updated.Deactivated():
+		h.auditProvisioning("user.provision_reactivate", updated)
+	case !user.Deactivated() && updated.Deactivated():
+		h.auditProvisioning("user.provision_deactivate", updated)
+	}
+	if update.DisplayName != nil || update.Roles != nil {
+		h.auditProvisioning("user.provision_update", updated, "roles", updated.Roles)
+	}
+	WriteJSON(w, http.StatusOK, newUserResponse(updated))
+}
@@ -0,0 +1,209 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"bitriver-live/internal/authz"
+	"bitriver-live/internal/storage"
+)
+
+const testProvisioningToken = "provision-secret"
+
+func provisioningRequest(method, path, body string) *http.Request {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	req.Header.Set("Authorization", "Bearer "+testProvisioningToken)
+	return req
+}
+
+func TestProvisioningRequiresItsOwnToken(t *testing.T) {
+	handler, store := newTestHandler(t)
+	admin, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Admin", Email: "admin@example.com", Roles: []string{authz.RoleAdmin}})
+	if err != nil {
+		t.Fatalf("CreateUser admin: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ProvisioningUsers(rec, provisioningRequest(http.MethodGet, "/api/admin/provisioning/users", ""))
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 while provisioning is disabled, got %d", rec.Code)
+	}
+
+	handler.ProvisioningToken = testProvisioningToken
+	session, _, err := handler.sessionManager().Create(admin.ID)
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	for name, req := range map[string]*http.Request{
+		"admin session": func() *http.Request {
+			req := withUser(httptest.NewRequest(http.MethodGet, "/api/admin/provisioning/users", nil), admin)
+			req.Header.Set("Authorization", "Bearer "+session)
+			return req
+		}(),
+		"wrong token": func() *http.Request {
+			req := httptest.NewRequest(http.MethodGet, "/api/admin/provisioning/users", nil)
+			req.Header.Set("Authorization", "Bearer "+testProvisioningToken+"x")
+			return req
+		}(),
+		"query token": httptest.NewRequest(http.MethodGet, "/api/admin/provisioning/users?token="+testProvisioningToken, nil),
+	} {
+		rec := httptest.NewRecorder()
+		handler.ProvisioningUsers(rec, req)
+		if rec.Code != http.StatusUnauthorized {
+			t.Fatalf("%s: expected 401, got %d", name, rec.Code)
+		}
+	}
+
+	if _, _, err := handler.AuthenticateRequest(provisioningRequest(http.MethodGet, "/api/users", "")); err == nil {
+		t.Fatal("expected the provisioning token to be refused as a session")
+	}
+}
+
+func TestProvisioningUpsertsByEmail(t *testing.T) {
+	handler, store := newTestHandler(t)
+	handler.ProvisioningToken = testProvisioningToken
+	var audit bytes.Buffer
+	handler.AuditLogger = slog.New(slog.NewJSONHandler(&audit, nil))
+
+	rec := httptest.NewRecorder()
+	handler.ProvisioningUsers(rec, provisioningRequest(http.MethodPost, "/api/admin/provisioning/users", `{"displayName":"Dana","email":"Dana@Corp.example","roles":["creator"]}`))
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created userResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode created user: %v", err)
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ProvisioningUsers(rec, provisioningRequest(http.MethodPost, "/api/admin/provisioning/users", `{"displayName":"Dana R.","email":"dana@corp.example","roles":["creator","moderator"]}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 re-provisioning an existing email, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var again userResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &again); err != nil {
+		t.Fatalf("decode re-provisioned user: %v", err)
+	}
+	if again.ID != created.ID || again.DisplayName != "Dana R." || strings.Join(again.Roles, ",") != "creator,moderator" {
+		t.Fatalf("expected %s updated in place, got %+v", created.ID, again)
+	}
+	users, err := store.ListUsers()
+	if err != nil {
+		t.Fatalf("ListUsers: %v", err)
+	}
+	if len(users) != 1 {
+		t.Fatalf("expected a single user after re-provisioning, got %d", len(users))
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ProvisioningUsers(rec, provisioningRequest(http.MethodGet, "/api/admin/provisioning/users?email=DANA@corp.example", ""))
+	var page struct {
+		Items []userResponse `json:"items"`
+		Total int            `json:"total"`
+	}
+	if err := json.Unmarshal(rec.Body.Bytes(), &page); err != nil {
+		t.Fatalf("decode page: %v", err)
+	}
+	if rec.Code != http.StatusOK || page.Total != 1 || page.Items[0].ID != created.ID {
+		t.Fatalf("expected email lookup to find %s, got %d %+v", created.ID, rec.Code, page)
+	}
+
+	var events []map[string]any
+	for _, line := range strings.Split(strings.TrimSpace(audit.String()), "\n") {
+		var event map[string]any
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			t.Fatalf("decode audit line %q: %v", line, err)
+		}
+		events = append(events, event)
+	}
+	if len(events) != 2 || events[0]["action"] != "user.provision_create" || events[1]["action"] != "user.provision_update" {
+		t.Fatalf("expected create and update audit events, got %v", events)
+	}
+	for _, event := range events {
+		if event["client"] != provisioningClient || event["target_user_id"] != created.ID {
+			t.Fatalf("expected events attributed to the provisioning client, got %v", event)
+		}
+		if _, ok := event["user_id"]; ok {
+			t.Fatalf("expected no user actor on provisioning events, got %v", event)
+		}
+	}
+}
+
+func TestProvisioningDeactivationRevokesAccess(t *testing.T) {
+	handler, store := newTestHandler(t)
+	handler.ProvisioningToken = testProvisioningToken
+	var audit bytes.Buffer
+	handler.AuditLogger = slog.New(slog.NewJSONHandler(&audit, nil))
+
+	user, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Leaver", Email: "leaver@corp.example", Password: "correct-horse", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if _, err := store.CreateChannel(user.ID, "Leaver's channel", "gaming", nil); err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	session, _, err := handler.sessionManager().Create(user.ID)
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ProvisioningUserByID(rec, provisioningRequest(http.MethodDelete, "/api/admin/provisioning/users/"+user.ID, ""))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d: %s", rec.Code, rec.Body.String())
+	}
+	stored, ok := store.GetUser(user.ID)
+	if !ok || !stored.Deactivated() {
+		t.Fatalf("expected user kept and deactivated, got %+v", stored)
+	}
+	channels, err := store.ListChannels(user.ID, "")
+	if err != nil {
+		t.Fatalf("ListChannels: %v", err)
+	}
+	if len(channels) != 1 {
+		t.Fatalf("expected the user's channel to survive deactivation, got %d", len(channels))
+	}
+	if !strings.Contains(audit.String(), `"action":"user.provision_deactivate"`) {
+		t.Fatalf("expected deactivation audit event, got %s", audit.String())
+	}
+
+	if _, _, ok, err := handler.sessionManager().Validate(session); err != nil || ok {
+		t.Fatalf("expected session to be revoked, got ok=%v err=%v", ok, err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(`{"email":"leaver@corp.example","password":"correct-horse"}`))
+	rec = httptest.NewRecorder()
+	handler.Login(rec, req)
+	if rec.Code != http.StatusForbidden || decodeAPIError(t, rec.Body.Bytes()).Error.Code != "account_deactivated" {
+		t.Fatalf("expected 403 account_deactivated at login, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = httptest.NewRecorder()
+	handler.ProvisioningUserByID(rec, provisioningRequest(http.MethodPatch, "/api/admin/provisioning/users/"+user.ID, `{"active":true}`))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 reactivating, got %d: %s", rec.Code, rec.Body.String())
+	}
+	session, _, err = handler.sessionManager().Create(user.ID)
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	authed := httptest.NewRequest(http.MethodGet, "/api/users/me", nil)
+	authed.Header.Set("Authorization", "Bearer "+session)
+	if _, _, err := handler.AuthenticateRequest(authed); err != nil {
+		t.Fatalf("expected reactivated user to authenticate, got %v", err)
+	}
+
+	// A session that outlives deactivation, as with stores that cannot
+	// revoke by user, is refused when it is next presented.
+	deactivate := true
+	if _, err := store.UpdateUser(user.ID, storage.UserUpdate{Deactivated: &deactivate}); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	if _, _, err := handler.AuthenticateRequest(authed); err == nil {
+		t.Fatal("expected a deactivated user's session to be refused")
+	}
+}
@@ -0,0 +1,72 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+)
+
+// Read cache namespaces. Only payloads that are identical for every viewer
+// belong here: directory listings and the public channel projection, never
+// stream keys or owner-only fields.
+const (
+	directoryCacheNamespace = "directory"
+	channelCacheNamespace   = "channel"
+)
+
+// writeCachedJSON serves a 200 JSON response from the read cache, calling build
+// on a miss. Errors returned by build are written with WriteRequestError and
+// are not cached. Entries keep the payload's ETag on their first line, so a
+// hit answers conditional requests without encoding or hashing anything.
+func (h *Handler) writeCachedJSON(w http.ResponseWriter, r *http.Request, conditional conditionalGET, namespace, key string, build func() (interface{}, error)) {
+	entry, err := h.ReadCache.GetOrLoad(r.Context(), namespace, key, func() ([]byte, error) {
+		payload, err := build()
+		if err != nil {
+			return nil, err
+		}
+		etag, body, err := encodeETagged(payload)
+		if err != nil {
+			return nil, err
+		}
+		return append([]byte(etag+"\n"), body...), nil
+	})
+	if err != nil {
+		if reqErr, ok := storageRequestError(err); ok {
+			err = reqErr
+		}
+		WriteRequestError(w, err)
+		return
+	}
+	etag, body := splitCachedEntry(entry)
+	conditional.write(w, r, etag, body)
+}
+
+// splitCachedEntry separates a read cache entry into its ETag and body.
+// Entries written before ETags were stored hold only the body and are
+// hashed as they are.
+func splitCachedEntry(entry []byte) (string, []byte) {
+	if len(entry) > 0 && entry[0] == '"' {
+		if i := bytes.IndexByte(entry, '\n'); i > 0 {
+			return string(entry[:i]), entry[i+1:]
+		}
+	}
+	return etagFor(entry), entry
+}
+
+// invalidateDirectoryCache drops every cached directory listing after a write
+// that changes what or how channels are listed.
+func (h *Handler) invalidateDirectoryCache(ctx context.Context) {
+	if err := h.ReadCache.Invalidate(ctx, directoryCacheNamespace); err != nil {
+		h.logger().Warn("failed to invalidate directory cache", "error", err)
+	}
+}
+
+// invalidateChannelCache drops the channel's cached public payload and live
+// status along with the directory listings that embed them.
+func (h *Handler) invalidateChannelCache(ctx context.Context, channelID string) {
+	h.statuses.invalidate(channelID)
+	if err := h.ReadCache.InvalidateKey(ctx, channelCacheNamespace, channelID); err != nil {
+		h.logger().Warn("failed to invalidate channel cache", "channel_id", channelID, "error", err)
+	}
+	h.invalidateDirectoryCache(ctx)
+}
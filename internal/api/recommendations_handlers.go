@@ -0,0 +1,103 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+// recommendationReasonResponse explains a recommendation. Kind is one of the
+// storage.RecommendationReason values and Label the sentence clients show.
+type recommendationReasonResponse struct {
+	Kind      string `json:"kind"`
+	Label     string `json:"label"`
+	ChannelID string `json:"channelId,omitempty"`
+	Category  string `json:"category,omitempty"`
+}
+
+type recommendedChannelResponse struct {
+	directoryChannelResponse
+	Reason recommendationReasonResponse `json:"reason"`
+}
+
+type recommendationsResponse struct {
+	Channels    []recommendedChannelResponse `json:"channels"`
+	GeneratedAt string                       `json:"generatedAt"`
+}
+
+// etagContent leaves GeneratedAt out of the recommendations' ETag.
+func (r recommendationsResponse) etagContent() interface{} {
+	return r.Channels
+}
+
+// userRecommendations serves GET /api/users/me/recommendations: channels the
+// signed-in user does not follow, ranked by what viewers with overlapping
+// follows watch and by the categories they follow. The optional limit
+// query parameter caps the list at storage.MaxRecommendationLimit.
+func (h *Handler) userRecommendations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	user, ok := h.requireAuthenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	limit := storage.DefaultRecommendationLimit
+	if raw := strings.TrimSpace(r.URL.Query().Get("limit")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			WriteError(w, http.StatusBadRequest, fmt.Errorf("limit must be a positive integer"))
+			return
+		}
+		limit = parsed
+	}
+
+	recommendations, err := h.Store.RecommendChannels(user.ID, limit)
+	if err != nil {
+		writeStorageError(w, http.StatusInternalServerError, err)
+		return
+	}
+	response := recommendationsResponse{
+		Channels:    make([]recommendedChannelResponse, 0, len(recommendations)),
+		GeneratedAt: time.Now().UTC().Format(time.RFC3339Nano),
+	}
+	for _, recommendation := range recommendations {
+		channel, exists := h.Store.GetChannel(recommendation.ChannelID)
+		if !exists {
+			continue
+		}
+		entries := h.buildDirectoryResponse([]models.Channel{channel}).Channels
+		if len(entries) == 0 {
+			continue
+		}
+		response.Channels = append(response.Channels, recommendedChannelResponse{
+			directoryChannelResponse: entries[0],
+			Reason:                   h.recommendationReason(recommendation),
+		})
+	}
+	h.writeETagJSON(w, r, viewerETag("recommendations"), response)
+}
+
+// recommendationReason labels a recommendation with the signal behind it.
+func (h *Handler) recommendationReason(recommendation storage.ChannelRecommendation) recommendationReasonResponse {
+	reason := recommendationReasonResponse{Kind: recommendation.Reason, Category: recommendation.Category}
+	switch recommendation.Reason {
+	case storage.RecommendationReasonFollowOverlap:
+		reason.ChannelID = recommendation.ViaChannelID
+		reason.Label = "Followed by viewers like you"
+		if via, ok := h.Store.GetChannel(recommendation.ViaChannelID); ok {
+			reason.Label = "Because you follow " + via.Title
+		}
+	case storage.RecommendationReasonCategory:
+		reason.Label = "Popular in " + recommendation.Category
+	default:
+		reason.Label = "Popular right now"
+	}
+	return reason
+}
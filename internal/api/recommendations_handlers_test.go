@@ -0,0 +1,97 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"testing"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+func TestUserRecommendationsReasons(t *testing.T) {
+	handler, store := newTestHandler(t)
+	users := make(map[string]models.User)
+	for _, name := range []string{"viewer", "peer", "creator", "newcomer"} {
+		user, err := store.CreateUser(storage.CreateUserParams{DisplayName: name, Email: name + "@example.com"})
+		if err != nil {
+			t.Fatalf("CreateUser %s: %v", name, err)
+		}
+		users[name] = user
+	}
+	channels := make(map[string]models.Channel)
+	for _, spec := range [][2]string{{"Alpha", "gaming"}, {"Beta", "art"}, {"Gamma", "gaming"}} {
+		channel, err := store.CreateChannel(users["creator"].ID, spec[0], spec[1], nil)
+		if err != nil {
+			t.Fatalf("CreateChannel %s: %v", spec[0], err)
+		}
+		channels[spec[0]] = channel
+	}
+	for _, follow := range [][2]string{{"viewer", "Alpha"}, {"peer", "Alpha"}, {"peer", "Beta"}} {
+		if err := store.FollowChannel(users[follow[0]].ID, channels[follow[1]].ID); err != nil {
+			t.Fatalf("FollowChannel: %v", err)
+		}
+	}
+	if _, err := store.StartStream(channels["Beta"].ID, []string{"720p"}); err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+
+	recommend := func(user models.User, query string) (*httptest.ResponseRecorder, recommendationsResponse) {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, "/api/users/me/recommendations"+query, nil)
+		rec := httptest.NewRecorder()
+		handler.UserByID(rec, withUser(req, user))
+		var response recommendationsResponse
+		if rec.Code == http.StatusOK {
+			if err := json.Unmarshal(rec.Body.Bytes(), &response); err != nil {
+				t.Fatalf("decode recommendations: %v", err)
+			}
+		}
+		return rec, response
+	}
+	reasons := func(response recommendationsResponse) []recommendationReasonResponse {
+		out := make([]recommendationReasonResponse, 0, len(response.Channels))
+		for _, channel := range response.Channels {
+			out = append(out, channel.Reason)
+		}
+		return out
+	}
+
+	rec, response := recommend(users["viewer"], "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	want := []recommendationReasonResponse{
+		{Kind: storage.RecommendationReasonFollowOverlap, Label: "Because you follow Alpha", ChannelID: channels["Alpha"].ID, Category: "art"},
+		{Kind: storage.RecommendationReasonCategory, Label: "Popular in gaming", Category: "gaming"},
+	}
+	if got := reasons(response); !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected reasons:\n got %+v\nwant %+v", got, want)
+	}
+	if response.Channels[0].Channel.ID != channels["Beta"].ID || !response.Channels[0].Live || response.Channels[0].Owner.ID != users["creator"].ID {
+		t.Fatalf("expected the live Beta channel first, got %+v", response.Channels[0])
+	}
+
+	_, response = recommend(users["viewer"], "?limit=1")
+	if len(response.Channels) != 1 {
+		t.Fatalf("expected the limit to apply, got %d channels", len(response.Channels))
+	}
+
+	_, response = recommend(users["newcomer"], "")
+	if got := reasons(response); len(got) != 1 || got[0].Label != "Popular right now" || response.Channels[0].Channel.ID != channels["Beta"].ID {
+		t.Fatalf("expected the popular live channel for a newcomer, got %+v", got)
+	}
+
+	if rec, _ := recommend(users["viewer"], "?limit=zero"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a bad limit, got %d", rec.Code)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users/me/recommendations", nil)
+	rec = httptest.NewRecorder()
+	handler.UserByID(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a session, got %d", rec.Code)
+	}
+}
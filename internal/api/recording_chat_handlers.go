@@ -0,0 +1,225 @@
+package api
+
+import (
+	"encoding/base64"
+	"fmt"
+	"math"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/chat"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+const (
+	defaultChatReplayPageSize = 200
+	// defaultChatReplayWindow is used when toSeconds is omitted and the
+	// recording has no known duration.
+	defaultChatReplayWindow = 5 * time.Minute
+	// chatReplayCacheMaxAge is how long clients may reuse a historical
+	// window before revalidating; moderators can still delete messages
+	// after the fact, so the ETag tracks the window contents.
+	chatReplayCacheMaxAge = time.Minute
+)
+
+type recordingChatMessageResponse struct {
+	ID            string       `json:"id"`
+	UserID        string       `json:"userId"`
+	Content       string       `json:"content"`
+	CreatedAt     string       `json:"createdAt"`
+	OffsetSeconds float64      `json:"offsetSeconds"`
+	Author        *chat.Author `json:"author,omitempty"`
+}
+
+type recordingChatResponse struct {
+	RecordingID string                         `json:"recordingId"`
+	FromSeconds float64                        `json:"fromSeconds"`
+	ToSeconds   float64                        `json:"toSeconds"`
+	Messages    []recordingChatMessageResponse `json:"messages"`
+	NextCursor  string                         `json:"nextCursor,omitempty"`
+}
+
+// recordingChatReplay serves the chat messages posted during a window of a
+// recording, expressed as offsets from the start of the recorded session.
+// Callers must have already enforced recording visibility.
+func (h *Handler) recordingChatReplay(w http.ResponseWriter, r *http.Request, recording models.Recording) {
+	query := r.URL.Query()
+	fromSeconds, err := parseReplayOffset(query.Get("fromSeconds"), 0)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, fmt.Errorf("fromSeconds %v", err))
+		return
+	}
+	defaultTo := fromSeconds + defaultChatReplayWindow.Seconds()
+	if recording.DurationSeconds > 0 {
+		defaultTo = float64(recording.DurationSeconds)
+	}
+	toSeconds, err := parseReplayOffset(query.Get("toSeconds"), defaultTo)
+	if err != nil {
+		WriteError(w, http.StatusBadRequest, fmt.Errorf("toSeconds %v", err))
+		return
+	}
+	if toSeconds <= fromSeconds {
+		WriteError(w, http.StatusBadRequest, fmt.Errorf("toSeconds must be greater than fromSeconds"))
+		return
+	}
+	limit := defaultChatReplayPageSize
+	if raw := strings.TrimSpace(query.Get("limit")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed <= 0 {
+			WriteError(w, http.StatusBadRequest, fmt.Errorf("limit must be a positive integer"))
+			return
+		}
+		limit = parsed
+	}
+	if limit > storage.MaxChatReplayPageSize {
+		limit = storage.MaxChatReplayPageSize
+	}
+
+	sessionStart := h.recordingSessionStart(recording)
+	windowStart := sessionStart.Add(secondsToDuration(fromSeconds))
+	windowEnd := sessionStart.Add(secondsToDuration(toSeconds))
+	params := storage.ChatMessageRangeParams{
+		ChannelID: recording.ChannelID,
+		Start:     windowStart,
+		End:       windowEnd,
+		Limit:     limit,
+	}
+	if cursor := strings.TrimSpace(query.Get("cursor")); cursor != "" {
+		createdAt, id, err := decodeChatReplayCursor(cursor)
+		if err != nil {
+			writeStorageError(w, http.StatusBadRequest, err)
+			return
+		}
+		params.AfterCreatedAt = createdAt
+		params.AfterID = id
+	}
+
+	messages, err := h.Store.ListChatMessagesInRange(params)
+	if err != nil {
+		writeStorageError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	var authors map[string]chat.Author
+	if channel, ok := h.Store.GetChannel(recording.ChannelID); ok {
+		authors, err = h.chatAuthors(channel, messages)
+		if err != nil {
+			WriteError(w, http.StatusInternalServerError, fmt.Errorf("resolve chat authors: %w", err))
+			return
+		}
+	}
+
+	response := recordingChatResponse{
+		RecordingID: recording.ID,
+		FromSeconds: fromSeconds,
+		ToSeconds:   toSeconds,
+		Messages:    make([]recordingChatMessageResponse, 0, len(messages)),
+	}
+	for _, message := range messages {
+		// Messages from viewers who were later banned are hidden from
+		// replay the same way the live room drops them.
+		if h.Store.IsChatBanned(recording.ChannelID, message.UserID) {
+			continue
+		}
+		entry := recordingChatMessageResponse{
+			ID:            message.ID,
+			UserID:        message.UserID,
+			Content:       message.Content,
+			CreatedAt:     message.CreatedAt.Format(time.RFC3339Nano),
+			OffsetSeconds: message.CreatedAt.Sub(sessionStart).Seconds(),
+		}
+		if author, ok := authors[message.UserID]; ok {
+			entry.Author = &author
+		}
+		response.Messages = append(response.Messages, entry)
+	}
+	// The cursor follows the last stored message rather than the last one
+	// returned so filtered messages do not stall pagination.
+	if len(messages) == limit {
+		last := messages[len(messages)-1]
+		response.NextCursor = encodeChatReplayCursor(last.CreatedAt, last.ID)
+	}
+
+	body, err := marshalResponse(response)
+	if err != nil {
+		WriteError(w, http.StatusInternalServerError, fmt.Errorf("encode chat replay: %w", err))
+		return
+	}
+	if !windowEnd.After(h.now()) {
+		etag := etagFor(body)
+		visibility := "private"
+		if recording.PublishedAt != nil {
+			visibility = "public"
+		}
+		w.Header().Set("ETag", etag)
+		w.Header().Set("Last-Modified", windowEnd.UTC().Format(http.TimeFormat))
+		w.Header().Set("Cache-Control", fmt.Sprintf("%s, max-age=%d", visibility, int(chatReplayCacheMaxAge.Seconds())))
+		if match := r.Header.Get("If-None-Match"); match != "" && etagMatches(match, etag) {
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+	} else {
+		// The window is still open, so new messages may yet arrive.
+		w.Header().Set("Cache-Control", "no-cache")
+	}
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(http.StatusOK)
+	_, _ = w.Write(append(body, '\n'))
+}
+
+// recordingSessionStart resolves when the recorded session began. Older
+// recordings whose session has been pruned fall back to deriving the start
+// from the recording's creation time and duration.
+func (h *Handler) recordingSessionStart(recording models.Recording) time.Time {
+	if sessions, err := h.Store.ListStreamSessions(recording.ChannelID); err == nil {
+		for _, session := range sessions {
+			if session.ID == recording.SessionID {
+				return session.StartedAt.UTC()
+			}
+		}
+	}
+	return recording.CreatedAt.Add(-time.Duration(recording.DurationSeconds) * time.Second).UTC()
+}
+
+func parseReplayOffset(raw string, fallback float64) (float64, error) {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" {
+		return fallback, nil
+	}
+	value, err := strconv.ParseFloat(trimmed, 64)
+	if err != nil || math.IsNaN(value) || math.IsInf(value, 0) {
+		return 0, fmt.Errorf("must be a number")
+	}
+	if value < 0 {
+		return 0, fmt.Errorf("must not be negative")
+	}
+	return value, nil
+}
+
+func secondsToDuration(seconds float64) time.Duration {
+	return time.Duration(seconds * float64(time.Second))
+}
+
+func encodeChatReplayCursor(createdAt time.Time, id string) string {
+	raw := strconv.FormatInt(createdAt.UnixNano(), 10) + ":" + id
+	return base64.RawURLEncoding.EncodeToString([]byte(raw))
+}
+
+func decodeChatReplayCursor(cursor string) (time.Time, string, error) {
+	decoded, err := base64.RawURLEncoding.DecodeString(cursor)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("invalid cursor")
+	}
+	nanos, id, ok := strings.Cut(string(decoded), ":")
+	if !ok || id == "" {
+		return time.Time{}, "", fmt.Errorf("invalid cursor")
+	}
+	value, err := strconv.ParseInt(nanos, 10, 64)
+	if err != nil {
+		return time.Time{}, "", fmt.Errorf("invalid cursor")
+	}
+	return time.Unix(0, value).UTC(), id, nil
+}
@@ -0,0 +1,224 @@
+package api
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/authz"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+// maxRecordingBatchSize caps how many recordings one batch request may name.
+const maxRecordingBatchSize = 100
+
+type recordingUpdateRequest struct {
+	Title       *string   `json:"title"`
+	Description *string   `json:"description"`
+	Tags        *[]string `json:"tags"`
+	// PremiereAt is an RFC 3339 time to premiere the recording at, or an
+	// empty string to cancel a scheduled premiere.
+	PremiereAt *string `json:"premiereAt"`
+}
+
+type recordingChaptersRequest struct {
+	Chapters []recordingChapterResponse `json:"chapters"`
+}
+
+type recordingBatchRequest struct {
+	RecordingIDs []string  `json:"recordingIds"`
+	Description  *string   `json:"description"`
+	Tags         *[]string `json:"tags"`
+	AddTags      []string  `json:"addTags"`
+	RemoveTags   []string  `json:"removeTags"`
+}
+
+type recordingBatchResultResponse struct {
+	RecordingID string `json:"recordingId"`
+	Status      string `json:"status"`
+	Error       string `json:"error,omitempty"`
+}
+
+type recordingBatchResponse struct {
+	Results []recordingBatchResultResponse `json:"results"`
+	Updated int                            `json:"updated"`
+	Skipped int                            `json:"skipped"`
+	Failed  int                            `json:"failed"`
+}
+
+// recordingTagFilter reads the tag query parameters of a recording listing.
+// Tags may repeat the parameter or be comma separated.
+func recordingTagFilter(r *http.Request) []string {
+	var tags []string
+	for _, value := range r.URL.Query()["tag"] {
+		tags = append(tags, strings.Split(value, ",")...)
+	}
+	return tags
+}
+
+// updateRecording serves PATCH /api/recordings/{id}, which edits a
+// recording's title, description, and tags. Recording listings are not
+// cached, so edits show up in public listings straight away. Only the
+// channel owner and admins may schedule a premiere; editors cannot decide
+// when a recording goes public.
+func (h *Handler) updateRecording(recording models.Recording, channel models.Channel, w http.ResponseWriter, r *http.Request) {
+	actor, ok := h.requireAuthenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	if !h.canManageChannelMedia(actor, channel) {
+		WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
+		return
+	}
+	if !h.requireScope(w, r, storage.APITokenScopeManageChannel) {
+		return
+	}
+	var req recordingUpdateRequest
+	if !DecodeAndValidate(w, r, &req) {
+		return
+	}
+	update := storage.RecordingUpdate{
+		Title:       req.Title,
+		Description: req.Description,
+		Tags:        req.Tags,
+	}
+	if req.PremiereAt != nil {
+		if channel.OwnerID != actor.ID && !authz.Has(actor, authz.RecordingsManageAny) {
+			WriteError(w, http.StatusForbidden, fmt.Errorf("only the channel owner can schedule a premiere"))
+			return
+		}
+		var premiereAt time.Time
+		if value := strings.TrimSpace(*req.PremiereAt); value != "" {
+			parsed, err := time.Parse(time.RFC3339, value)
+			if err != nil {
+				WriteError(w, http.StatusBadRequest, fmt.Errorf("premiereAt must be an RFC 3339 timestamp"))
+				return
+			}
+			premiereAt = parsed
+		}
+		update.PremiereAt = &premiereAt
+	}
+	updated, err := h.Store.UpdateRecording(recording.ID, update)
+	if err != nil {
+		writeStorageError(w, http.StatusBadRequest, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, newRecordingResponse(updated))
+}
+
+// setRecordingChapters serves PUT /api/recordings/{id}/chapters, which
+// replaces all of a recording's chapters. An empty list removes them.
+func (h *Handler) setRecordingChapters(recording models.Recording, channel models.Channel, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		WriteMethodNotAllowed(w, r, http.MethodPut)
+		return
+	}
+	actor, ok := h.requireAuthenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	if !h.canManageChannelMedia(actor, channel) {
+		WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
+		return
+	}
+	if !h.requireScope(w, r, storage.APITokenScopeManageChannel) {
+		return
+	}
+	var req recordingChaptersRequest
+	if !DecodeAndValidate(w, r, &req) {
+		return
+	}
+	chapters := make([]models.RecordingChapter, 0, len(req.Chapters))
+	for _, chapter := range req.Chapters {
+		chapters = append(chapters, models.RecordingChapter(chapter))
+	}
+	updated, err := h.Store.SetRecordingChapters(recording.ID, chapters)
+	if err != nil {
+		writeStorageError(w, http.StatusBadRequest, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, newRecordingResponse(updated))
+}
+
+// handleChannelRecordings serves POST /api/channels/{id}/recordings/batch,
+// which edits the description and tags of up to maxRecordingBatchSize of the
+// channel's recordings at once. Every modified recording is written to the
+// audit log.
+func (h *Handler) handleChannelRecordings(channel models.Channel, remaining []string, w http.ResponseWriter, r *http.Request) {
+	if len(remaining) != 1 || remaining[0] != "batch" {
+		WriteError(w, http.StatusNotFound, fmt.Errorf("unknown recordings path"))
+		return
+	}
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+	actor, ok := h.requireAuthenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	if !h.canManageChannelMedia(actor, channel) {
+		WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
+		return
+	}
+	if !h.requireScope(w, r, storage.APITokenScopeManageChannel) {
+		return
+	}
+	var req recordingBatchRequest
+	if !DecodeAndValidate(w, r, &req) {
+		return
+	}
+	if len(req.RecordingIDs) == 0 {
+		WriteRequestError(w, ValidationError("recordingIds is required"))
+		return
+	}
+	if len(req.RecordingIDs) > maxRecordingBatchSize {
+		WriteRequestError(w, ValidationError(fmt.Sprintf("at most %d recordings may be updated per batch", maxRecordingBatchSize)))
+		return
+	}
+
+	results, err := h.Store.BatchUpdateRecordings(channel.ID, req.RecordingIDs, storage.RecordingBatchUpdate{
+		Description: req.Description,
+		Tags:        req.Tags,
+		AddTags:     req.AddTags,
+		RemoveTags:  req.RemoveTags,
+	})
+	if err != nil {
+		writeStorageError(w, http.StatusBadRequest, err)
+		return
+	}
+
+	response := recordingBatchResponse{Results: make([]recordingBatchResultResponse, 0, len(results))}
+	for _, result := range results {
+		response.Results = append(response.Results, recordingBatchResultResponse{
+			RecordingID: result.RecordingID,
+			Status:      result.Status,
+			Error:       result.Error,
+		})
+		switch result.Status {
+		case storage.RecordingBatchUpdated:
+			response.Updated++
+			fields := []any{"action", "recording.batch_update", "user_id", actor.ID, "channel_id", channel.ID, "recording_id", result.RecordingID}
+			if req.Description != nil {
+				fields = append(fields, "description_changed", true)
+			}
+			if req.Tags != nil {
+				fields = append(fields, "tags", *req.Tags)
+			}
+			if len(req.AddTags) > 0 {
+				fields = append(fields, "add_tags", req.AddTags)
+			}
+			if len(req.RemoveTags) > 0 {
+				fields = append(fields, "remove_tags", req.RemoveTags)
+			}
+			h.auditLogger().Info("audit", fields...)
+		case storage.RecordingBatchSkipped:
+			response.Skipped++
+		default:
+			response.Failed++
+		}
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
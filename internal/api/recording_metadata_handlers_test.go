@@ -0,0 +1,219 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+func newPublishedRecordings(t *testing.T, store *storage.Storage, channelID string, count int) []models.Recording {
+	t.Helper()
+	for i := 0; i < count; i++ {
+		if _, err := store.StartStream(channelID, []string{"720p"}); err != nil {
+			t.Fatalf("StartStream: %v", err)
+		}
+		if _, err := store.StopStream(channelID, 10); err != nil {
+			t.Fatalf("StopStream: %v", err)
+		}
+	}
+	recordings, err := store.ListRecordings(channelID, true)
+	if err != nil || len(recordings) != count {
+		t.Fatalf("expected %d recordings, got %d (err %v)", count, len(recordings), err)
+	}
+	for i, recording := range recordings {
+		published, err := store.PublishRecording(recording.ID)
+		if err != nil {
+			t.Fatalf("PublishRecording: %v", err)
+		}
+		recordings[i] = published
+	}
+	return recordings
+}
+
+func listPublicRecordings(t *testing.T, h *Handler, channelID, query string) []recordingResponse {
+	t.Helper()
+	rec := httptest.NewRecorder()
+	h.Recordings(rec, httptest.NewRequest(http.MethodGet, "/api/recordings?channelId="+channelID+query, nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("list recordings: expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var recordings []recordingResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &recordings); err != nil {
+		t.Fatalf("decode recordings: %v", err)
+	}
+	return recordings
+}
+
+func TestUpdateRecordingNormalizesAndCapsMetadata(t *testing.T) {
+	h, store := newTestHandler(t)
+	owner, channel := newStatusChannel(t, store)
+	recording := newPublishedRecordings(t, store, channel.ID, 1)[0]
+
+	patch := func(user *models.User, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/api/recordings/"+recording.ID, strings.NewReader(body))
+		if user != nil {
+			req = withUser(req, *user)
+		}
+		rec := httptest.NewRecorder()
+		h.RecordingByID(rec, req)
+		return rec
+	}
+
+	if rec := patch(nil, `{"title":"Anonymous"}`); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a user, got %d", rec.Code)
+	}
+	rec := patch(&owner, `{"title":"  Finale  ","description":"The last run","tags":["Speedrun"," RETRO","speedrun"]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var updated recordingResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &updated); err != nil {
+		t.Fatalf("decode recording: %v", err)
+	}
+	if updated.Title != "Finale" || updated.Description != "The last run" || strings.Join(updated.Tags, ",") != "retro,speedrun" {
+		t.Fatalf("expected normalized metadata, got %+v", updated)
+	}
+
+	tooMany := make([]string, storage.MaxRecordingTags+1)
+	for i := range tooMany {
+		tooMany[i] = fmt.Sprintf("%q", fmt.Sprintf("tag%d", i))
+	}
+	for name, body := range map[string]string{
+		"empty title":      `{"title":"   "}`,
+		"long title":       `{"title":"` + strings.Repeat("t", storage.MaxRecordingTitleLength+1) + `"}`,
+		"long description": `{"description":"` + strings.Repeat("d", storage.MaxRecordingDescriptionLength+1) + `"}`,
+		"long tag":         `{"tags":["` + strings.Repeat("g", storage.MaxRecordingTagLength+1) + `"]}`,
+		"too many tags":    `{"tags":[` + strings.Join(tooMany, ",") + `]}`,
+		"nothing to do":    `{}`,
+	} {
+		if rec := patch(&owner, body); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d: %s", name, rec.Code, rec.Body.String())
+		}
+	}
+	if current, _ := store.GetRecording(recording.ID); current.Title != "Finale" || len(current.Tags) != 2 {
+		t.Fatalf("expected rejected edits to leave the recording unchanged, got %+v", current)
+	}
+}
+
+func TestBatchUpdateRecordingsReportsPerItemAndShowsInListings(t *testing.T) {
+	h, store := newTestHandler(t)
+	owner, channel := newStatusChannel(t, store)
+	recordings := newPublishedRecordings(t, store, channel.ID, 2)
+	first, second := recordings[0], recordings[1]
+	tagged := "speedrun"
+	if _, err := store.UpdateRecording(first.ID, storage.RecordingUpdate{Tags: &[]string{tagged}}); err != nil {
+		t.Fatalf("UpdateRecording: %v", err)
+	}
+
+	batch := func(body string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/recordings/batch", strings.NewReader(body)), owner)
+		rec := httptest.NewRecorder()
+		h.ChannelByID(rec, req)
+		return rec
+	}
+
+	ids := make([]string, maxRecordingBatchSize+1)
+	for i := range ids {
+		ids[i] = fmt.Sprintf(`"rec-%d"`, i)
+	}
+	if rec := batch(`{"recordingIds":[` + strings.Join(ids, ",") + `],"addTags":["x"]}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an oversized batch, got %d", rec.Code)
+	}
+	if rec := batch(`{"recordingIds":["` + first.ID + `"],"tags":["a"],"addTags":["b"]}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 when replacing and editing tags together, got %d", rec.Code)
+	}
+
+	rec := batch(`{"recordingIds":["` + first.ID + `","` + second.ID + `","missing"],"addTags":["Highlights"],"description":"Season one"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp recordingBatchResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode batch: %v", err)
+	}
+	if resp.Updated != 2 || resp.Failed != 1 || resp.Results[2].RecordingID != "missing" || resp.Results[2].Error == "" {
+		t.Fatalf("expected two updates and one failure, got %+v", resp)
+	}
+
+	if listed := listPublicRecordings(t, h, channel.ID, "&tag=HIGHLIGHTS"); len(listed) != 2 || listed[0].Description != "Season one" {
+		t.Fatalf("expected both recordings listed under the new tag, got %+v", listed)
+	}
+	listed := listPublicRecordings(t, h, channel.ID, "&tag=highlights,speedrun")
+	if len(listed) != 1 || listed[0].ID != first.ID {
+		t.Fatalf("expected only the recording with both tags, got %+v", listed)
+	}
+
+	rec = batch(`{"recordingIds":["` + first.ID + `"],"removeTags":["speedrun"]}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if listed := listPublicRecordings(t, h, channel.ID, "&tag=speedrun"); len(listed) != 0 {
+		t.Fatalf("expected a removed tag to drop out of public listings, got %+v", listed)
+	}
+}
+
+func TestSetRecordingChaptersReplacesAndShowsInPayload(t *testing.T) {
+	h, store := newTestHandler(t)
+	owner, channel := newStatusChannel(t, store)
+	viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	recording := newPublishedRecordings(t, store, channel.ID, 1)[0]
+
+	put := func(user *models.User, body string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPut, "/api/recordings/"+recording.ID+"/chapters", strings.NewReader(body))
+		if user != nil {
+			req = withUser(req, *user)
+		}
+		rec := httptest.NewRecorder()
+		h.RecordingByID(rec, req)
+		return rec
+	}
+
+	body := `{"chapters":[{"offsetSeconds":45,"title":" Boss "},{"offsetSeconds":0,"title":"Intro"}]}`
+	if rec := put(nil, body); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a user, got %d", rec.Code)
+	}
+	if rec := put(&viewer, body); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a viewer, got %d", rec.Code)
+	}
+	rec := put(&owner, body)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	for name, body := range map[string]string{
+		"missing title":   `{"chapters":[{"offsetSeconds":5,"title":" "}]}`,
+		"negative offset": `{"chapters":[{"offsetSeconds":-1,"title":"Before"}]}`,
+		"shared offset":   `{"chapters":[{"offsetSeconds":5,"title":"A"},{"offsetSeconds":5,"title":"B"}]}`,
+	} {
+		if rec := put(&owner, body); rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d: %s", name, rec.Code, rec.Body.String())
+		}
+	}
+
+	get := httptest.NewRecorder()
+	h.RecordingByID(get, httptest.NewRequest(http.MethodGet, "/api/recordings/"+recording.ID, nil))
+	if get.Code != http.StatusOK {
+		t.Fatalf("expected the published recording to load, got %d", get.Code)
+	}
+	var payload struct {
+		Chapters []map[string]any `json:"chapters"`
+	}
+	if err := json.Unmarshal(get.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode recording: %v", err)
+	}
+	if len(payload.Chapters) != 2 || payload.Chapters[0]["title"] != "Intro" || payload.Chapters[0]["offsetSeconds"] != float64(0) || payload.Chapters[1]["title"] != "Boss" || payload.Chapters[1]["offsetSeconds"] != float64(45) {
+		t.Fatalf("expected ordered chapters in the public payload, got %s", get.Body.String())
+	}
+
+	if rec := put(&owner, `{"chapters":[]}`); rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), `"chapters"`) {
+		t.Fatalf("expected an empty list to clear the chapters, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
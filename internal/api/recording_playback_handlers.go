@@ -0,0 +1,198 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+// defaultRecordingPlaybackTTL is the RecordingPlaybackTTL used when none is
+// configured.
+const defaultRecordingPlaybackTTL = 15 * time.Minute
+
+// recordingPlaybackGrantTTL is how long a viewer's playback authorization is
+// remembered, so refreshing expiring URLs skips the follow, subscription,
+// and age checks that issued them.
+const recordingPlaybackGrantTTL = time.Minute
+
+type recordingPlaybackResponse struct {
+	RecordingID string                       `json:"recordingId"`
+	MasterURL   string                       `json:"masterUrl,omitempty"`
+	Renditions  []recordingRenditionResponse `json:"renditions"`
+	ExpiresAt   *string                      `json:"expiresAt,omitempty"`
+}
+
+// recordingPlaybackGrants remembers which viewers were recently allowed to
+// play which recordings. The zero value is ready to use.
+type recordingPlaybackGrants struct {
+	mu      sync.Mutex
+	entries map[string]time.Time
+}
+
+func (g *recordingPlaybackGrants) allowed(key string, now time.Time) bool {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	grantedAt, ok := g.entries[key]
+	return ok && now.Sub(grantedAt) < recordingPlaybackGrantTTL
+}
+
+func (g *recordingPlaybackGrants) grant(key string, now time.Time) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if g.entries == nil {
+		g.entries = make(map[string]time.Time)
+	}
+	for existing, grantedAt := range g.entries {
+		if now.Sub(grantedAt) >= recordingPlaybackGrantTTL {
+			delete(g.entries, existing)
+		}
+	}
+	g.entries[key] = now
+}
+
+// recordingPlaybackGrantKey identifies a viewer of a recording: signed-in
+// viewers by account and anonymous ones by address.
+func recordingPlaybackGrantKey(recordingID string, actor models.User, hasActor bool, r *http.Request) string {
+	if hasActor {
+		return recordingID + "|user:" + actor.ID
+	}
+	return recordingID + "|ip:" + requestClientIP(r)
+}
+
+func (h *Handler) recordingPlaybackTTL() time.Duration {
+	if h.RecordingPlaybackTTL > 0 {
+		return h.RecordingPlaybackTTL
+	}
+	return defaultRecordingPlaybackTTL
+}
+
+// authorizeRecordingPlayback applies the recording's visibility rules:
+// unpublished recordings are limited to channel media managers, mature
+// channels to age-confirmed adults, and restricted channels to the
+// followers or subscribers they admit.
+func (h *Handler) authorizeRecordingPlayback(recording models.Recording, channel models.Channel, actor models.User, hasActor bool) error {
+	if recording.PublishedAt == nil {
+		if !hasActor {
+			return RequestError{Status: http.StatusUnauthorized, Message: "authentication required"}
+		}
+		if !h.canManageChannelMedia(actor, channel) {
+			return RequestError{Status: http.StatusForbidden, Message: "forbidden"}
+		}
+	}
+	if reason := h.recordingAgeGate(channel, actor, hasActor); reason != "" {
+		return ageGateError(reason)
+	}
+	if channel.PlaybackRestriction == "" {
+		return nil
+	}
+	var viewer *models.User
+	following := false
+	if hasActor {
+		viewer = &actor
+		following = h.Store.IsFollowingChannel(actor.ID, channel.ID)
+	}
+	state, err := h.subscriptionState(channel.ID, viewer)
+	if err != nil {
+		return err
+	}
+	if _, ok := playbackSubject(channel, viewer, following, state.Subscribed); !ok {
+		return RequestError{Status: http.StatusForbidden, CodeVal: playbackWithheldRestricted, Message: "this recording is limited to the channel's followers or subscribers"}
+	}
+	return nil
+}
+
+// handleRecordingPlayback serves /api/recordings/{id}/playback. GET returns
+// presigned rendition URLs with their expiry, GET master.m3u8 returns the
+// master playlist whose variants point at them, and POST refresh returns
+// new URLs to a viewer authorized within the last minute without checking
+// again. Recordings outside object storage, or in a bucket that cannot
+// presign, return their stored URLs with no expiry.
+func (h *Handler) handleRecordingPlayback(recording models.Recording, channel models.Channel, remaining []string, w http.ResponseWriter, r *http.Request) {
+	action := ""
+	if len(remaining) > 0 {
+		action = remaining[0]
+	}
+	if len(remaining) > 1 {
+		WriteError(w, http.StatusNotFound, fmt.Errorf("unknown recording path"))
+		return
+	}
+	switch action {
+	case "", "master.m3u8":
+		if r.Method != http.MethodGet {
+			WriteMethodNotAllowed(w, r, http.MethodGet)
+			return
+		}
+	case "refresh":
+		if r.Method != http.MethodPost {
+			WriteMethodNotAllowed(w, r, http.MethodPost)
+			return
+		}
+	default:
+		WriteError(w, http.StatusNotFound, fmt.Errorf("unknown recording path"))
+		return
+	}
+
+	actor, hasActor := UserFromContext(r.Context())
+	key := recordingPlaybackGrantKey(recording.ID, actor, hasActor, r)
+	now := h.now()
+	if action != "refresh" || !h.recordingPlaybackGrants.allowed(key, now) {
+		if err := h.authorizeRecordingPlayback(recording, channel, actor, hasActor); err != nil {
+			var reqErr RequestError
+			if errors.As(err, &reqErr) {
+				WriteRequestError(w, reqErr)
+				return
+			}
+			writeStorageError(w, http.StatusInternalServerError, err)
+			return
+		}
+		h.recordingPlaybackGrants.grant(key, now)
+	}
+
+	w.Header().Set("Cache-Control", "no-store")
+	playback, err := h.Store.RecordingPlayback(recording.ID, h.recordingPlaybackTTL())
+	if errors.Is(err, storage.ErrRecordingPlaybackUnavailable) {
+		if action == "master.m3u8" {
+			WriteError(w, http.StatusNotFound, fmt.Errorf("recording %s has no presigned playlist", recording.ID))
+			return
+		}
+		WriteJSON(w, http.StatusOK, recordingPlaybackResponse{
+			RecordingID: recording.ID,
+			Renditions:  newRecordingRenditionResponses(recording.Renditions),
+		})
+		return
+	}
+	if err != nil {
+		WriteError(w, http.StatusBadGateway, err)
+		return
+	}
+	if action == "master.m3u8" {
+		w.Header().Set("Content-Type", "application/vnd.apple.mpegurl")
+		w.WriteHeader(http.StatusOK)
+		_, _ = w.Write([]byte(playback.MasterPlaylist))
+		return
+	}
+	expiresAt := playback.ExpiresAt.Format(time.RFC3339Nano)
+	WriteJSON(w, http.StatusOK, recordingPlaybackResponse{
+		RecordingID: recording.ID,
+		MasterURL:   "/api/recordings/" + recording.ID + "/playback/master.m3u8",
+		Renditions:  newRecordingRenditionResponses(playback.Renditions),
+		ExpiresAt:   &expiresAt,
+	})
+}
+
+func newRecordingRenditionResponses(renditions []models.RecordingRendition) []recordingRenditionResponse {
+	response := make([]recordingRenditionResponse, 0, len(renditions))
+	for _, rendition := range renditions {
+		response = append(response, recordingRenditionResponse{
+			Name:        rendition.Name,
+			ManifestURL: rendition.ManifestURL,
+			Bitrate:     rendition.Bitrate,
+		})
+	}
+	return response
+}
@@ -0,0 +1,271 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+// presignedPlaybackRepository gives recordings archived renditions, presigns
+// them with a fake signer, and counts the follow lookups made while
+// authorizing viewers.
+type presignedPlaybackRepository struct {
+	storage.Repository
+	now         func() time.Time
+	unsigned    bool
+	signs       int
+	followCalls int
+}
+
+func (r *presignedPlaybackRepository) GetRecording(id string) (models.Recording, bool) {
+	recording, ok := r.Repository.GetRecording(id)
+	if ok {
+		recording.Renditions = []models.RecordingRendition{
+			{Name: "720p", ManifestURL: "https://origin.example.com/" + id + "/720p.m3u8", Bitrate: 3000},
+			{Name: "480p", ManifestURL: "https://origin.example.com/" + id + "/480p.m3u8", Bitrate: 1500},
+		}
+	}
+	return recording, ok
+}
+
+func (r *presignedPlaybackRepository) RecordingPlayback(id string, ttl time.Duration) (storage.RecordingPlayback, error) {
+	recording, ok := r.GetRecording(id)
+	if !ok || r.unsigned {
+		return storage.RecordingPlayback{}, storage.ErrRecordingPlaybackUnavailable
+	}
+	r.signs++
+	expires := r.now().Add(ttl)
+	playback := storage.RecordingPlayback{RecordingID: id, ExpiresAt: expires}
+	master := []string{"#EXTM3U"}
+	for _, rendition := range recording.Renditions {
+		rendition.ManifestURL = "https://signed.example.com/" + id + "/" + rendition.Name + ".m3u8?expires=" + expires.Format("150405")
+		playback.Renditions = append(playback.Renditions, rendition)
+		master = append(master, "#EXT-X-STREAM-INF:BANDWIDTH=1000", rendition.ManifestURL)
+	}
+	playback.MasterPlaylist = strings.Join(master, "\n") + "\n"
+	return playback, nil
+}
+
+func (r *presignedPlaybackRepository) IsFollowingChannel(userID, channelID string) bool {
+	r.followCalls++
+	return r.Repository.IsFollowingChannel(userID, channelID)
+}
+
+type recordingPlaybackFixture struct {
+	handler   *Handler
+	store     *storage.Storage
+	repo      *presignedPlaybackRepository
+	clock     *time.Time
+	owner     models.User
+	channel   models.Channel
+	recording models.Recording
+}
+
+func newRecordingPlaybackFixture(t *testing.T) recordingPlaybackFixture {
+	t.Helper()
+	handler, store := newTestHandler(t)
+	clock := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	f := recordingPlaybackFixture{handler: handler, store: store, clock: &clock}
+	handler.Now = func() time.Time { return *f.clock }
+	f.repo = &presignedPlaybackRepository{Repository: store, now: handler.Now}
+	handler.Store = f.repo
+	handler.RecordingPlaybackTTL = 5 * time.Minute
+
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser owner: %v", err)
+	}
+	f.owner = owner
+	channel, err := store.CreateChannel(owner.ID, "Archive", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	f.channel = channel
+	if _, err := store.StartStream(channel.ID, []string{"720p", "480p"}); err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	if _, err := store.StopStream(channel.ID, 30); err != nil {
+		t.Fatalf("StopStream: %v", err)
+	}
+	recordings, err := store.ListRecordings(channel.ID, true)
+	if err != nil || len(recordings) != 1 {
+		t.Fatalf("ListRecordings: %v (count %d)", err, len(recordings))
+	}
+	f.recording, _ = f.repo.GetRecording(recordings[0].ID)
+	return f
+}
+
+func (f recordingPlaybackFixture) publish(t *testing.T) {
+	t.Helper()
+	if _, err := f.store.PublishRecording(f.recording.ID); err != nil {
+		t.Fatalf("PublishRecording: %v", err)
+	}
+}
+
+func (f recordingPlaybackFixture) request(method, suffix string, user *models.User) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/api/recordings/"+f.recording.ID+"/playback"+suffix, nil)
+	if user != nil {
+		req = withUser(req, *user)
+	}
+	rec := httptest.NewRecorder()
+	f.handler.RecordingByID(rec, req)
+	return rec
+}
+
+func decodeRecordingPlayback(t *testing.T, rec *httptest.ResponseRecorder) recordingPlaybackResponse {
+	t.Helper()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var payload recordingPlaybackResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode playback: %v", err)
+	}
+	return payload
+}
+
+func TestRecordingPlaybackReturnsPresignedURLs(t *testing.T) {
+	f := newRecordingPlaybackFixture(t)
+	f.publish(t)
+
+	rec := f.request(http.MethodGet, "", nil)
+	if cc := rec.Header().Get("Cache-Control"); cc != "no-store" {
+		t.Fatalf("expected presigned playback to be uncacheable, got %q", cc)
+	}
+	payload := decodeRecordingPlayback(t, rec)
+	if payload.ExpiresAt == nil || *payload.ExpiresAt != "2024-06-01T12:05:00Z" {
+		t.Fatalf("expected URLs to expire with the configured TTL, got %v", payload.ExpiresAt)
+	}
+	if len(payload.Renditions) != 2 || !strings.HasPrefix(payload.Renditions[0].ManifestURL, "https://signed.example.com/") {
+		t.Fatalf("expected presigned renditions, got %+v", payload.Renditions)
+	}
+	if payload.MasterURL != "/api/recordings/"+f.recording.ID+"/playback/master.m3u8" {
+		t.Fatalf("unexpected master URL %q", payload.MasterURL)
+	}
+
+	master := f.request(http.MethodGet, "/master.m3u8", nil)
+	if master.Code != http.StatusOK {
+		t.Fatalf("expected master playlist, got %d: %s", master.Code, master.Body.String())
+	}
+	if ct := master.Header().Get("Content-Type"); ct != "application/vnd.apple.mpegurl" {
+		t.Fatalf("expected an HLS content type, got %q", ct)
+	}
+	if !strings.Contains(master.Body.String(), payload.Renditions[1].ManifestURL) {
+		t.Fatalf("expected master playlist to reference presigned variants:\n%s", master.Body.String())
+	}
+}
+
+func TestRecordingPlaybackFallsBackWithoutPresigning(t *testing.T) {
+	f := newRecordingPlaybackFixture(t)
+	f.publish(t)
+	f.repo.unsigned = true
+
+	payload := decodeRecordingPlayback(t, f.request(http.MethodGet, "", nil))
+	if payload.ExpiresAt != nil || payload.MasterURL != "" {
+		t.Fatalf("expected stored URLs without expiry, got %+v", payload)
+	}
+	if len(payload.Renditions) != len(f.recording.Renditions) || payload.Renditions[0].ManifestURL != f.recording.Renditions[0].ManifestURL {
+		t.Fatalf("expected the recording's stored renditions, got %+v", payload.Renditions)
+	}
+	if rec := f.request(http.MethodGet, "/master.m3u8", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected no master playlist without presigning, got %d", rec.Code)
+	}
+}
+
+func TestRecordingPlaybackRefreshReusesAuthorization(t *testing.T) {
+	f := newRecordingPlaybackFixture(t)
+	f.publish(t)
+	restriction := models.PlaybackRestrictionFollowers
+	if _, err := f.store.UpdateChannel(f.channel.ID, storage.ChannelUpdate{PlaybackRestriction: &restriction}); err != nil {
+		t.Fatalf("UpdateChannel: %v", err)
+	}
+	fan, err := f.store.CreateUser(storage.CreateUserParams{DisplayName: "Fan", Email: "fan@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser fan: %v", err)
+	}
+	if err := f.store.FollowChannel(fan.ID, f.channel.ID); err != nil {
+		t.Fatalf("FollowChannel: %v", err)
+	}
+
+	first := decodeRecordingPlayback(t, f.request(http.MethodGet, "", &fan))
+	if f.repo.followCalls != 1 {
+		t.Fatalf("expected the first request to check the follow, got %d lookups", f.repo.followCalls)
+	}
+
+	*f.clock = f.clock.Add(30 * time.Second)
+	refreshed := decodeRecordingPlayback(t, f.request(http.MethodPost, "/refresh", &fan))
+	if f.repo.followCalls != 1 {
+		t.Fatalf("expected refresh to reuse the authorization, got %d lookups", f.repo.followCalls)
+	}
+	if refreshed.ExpiresAt == nil || *refreshed.ExpiresAt <= *first.ExpiresAt {
+		t.Fatalf("expected refresh to extend the expiry, got %v after %v", refreshed.ExpiresAt, first.ExpiresAt)
+	}
+	if refreshed.Renditions[0].ManifestURL == first.Renditions[0].ManifestURL {
+		t.Fatal("expected refresh to return newly signed URLs")
+	}
+
+	// Once the remembered decision lapses the viewer is checked again, and
+	// an unfollow made in the meantime takes effect.
+	if err := f.store.UnfollowChannel(fan.ID, f.channel.ID); err != nil {
+		t.Fatalf("UnfollowChannel: %v", err)
+	}
+	*f.clock = f.clock.Add(recordingPlaybackGrantTTL)
+	rec := f.request(http.MethodPost, "/refresh", &fan)
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), playbackWithheldRestricted) {
+		t.Fatalf("expected lapsed refresh to be re-checked and refused, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if f.repo.followCalls != 2 {
+		t.Fatalf("expected a fresh follow lookup, got %d", f.repo.followCalls)
+	}
+	if rec := f.request(http.MethodGet, "/refresh", &fan); rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected refresh to require POST, got %d", rec.Code)
+	}
+}
+
+func TestRecordingPlaybackVisibility(t *testing.T) {
+	f := newRecordingPlaybackFixture(t)
+	viewer, err := f.store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser viewer: %v", err)
+	}
+
+	if rec := f.request(http.MethodGet, "", nil); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected anonymous viewers to be refused an unpublished recording, got %d", rec.Code)
+	}
+	if rec := f.request(http.MethodGet, "", &viewer); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected viewers to be refused an unpublished recording, got %d", rec.Code)
+	}
+	if rec := f.request(http.MethodPost, "/refresh", &viewer); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected refresh without a prior grant to be checked, got %d", rec.Code)
+	}
+	decodeRecordingPlayback(t, f.request(http.MethodGet, "", &f.owner))
+	if f.repo.signs != 1 {
+		t.Fatalf("expected only the owner's request to be signed, got %d", f.repo.signs)
+	}
+
+	f.publish(t)
+	mature := true
+	if _, err := f.store.UpdateChannel(f.channel.ID, storage.ChannelUpdate{MatureContent: &mature}); err != nil {
+		t.Fatalf("UpdateChannel: %v", err)
+	}
+	rec := f.request(http.MethodGet, "", &viewer)
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), playbackWithheldAgeConfirmation) {
+		t.Fatalf("expected the age gate to apply, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	mature = false
+	restriction := models.PlaybackRestrictionSubscribers
+	if _, err := f.store.UpdateChannel(f.channel.ID, storage.ChannelUpdate{MatureContent: &mature, PlaybackRestriction: &restriction}); err != nil {
+		t.Fatalf("UpdateChannel: %v", err)
+	}
+	if rec := f.request(http.MethodGet, "", &viewer); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected subscribers-only recordings to be refused, got %d", rec.Code)
+	}
+	decodeRecordingPlayback(t, f.request(http.MethodGet, "", &f.owner))
+}
@@ -21,6 +21,8 @@ type recordingResponse struct {
 	ChannelID       string                       `json:"channelId"`
 	SessionID       string                       `json:"sessionId"`
 	Title           string                       `json:"title"`
+	Description     string                       `json:"description,omitempty"`
+	Tags            []string                     `json:"tags,omitempty"`
 	DurationSeconds int                          `json:"durationSeconds"`
 	PlaybackBaseURL string                       `json:"playbackBaseUrl,omitempty"`
 	Renditions      []recordingRenditionResponse `json:"renditions,omitempty"`
@@ -29,7 +31,20 @@ type recordingResponse struct {
 	PublishedAt     *string                      `json:"publishedAt,omitempty"`
 	CreatedAt       string                       `json:"createdAt"`
 	RetainUntil     *string                      `json:"retainUntil,omitempty"`
+	SizeBytes       int64                        `json:"sizeBytes,omitempty"`
+	OverQuota       bool                         `json:"overQuota,omitempty"`
 	Clips           []clipExportSummaryResponse  `json:"clips,omitempty"`
+	Markers         []streamMarkerResponse       `json:"markers,omitempty"`
+	Chapters        []recordingChapterResponse   `json:"chapters,omitempty"`
+	PremiereAt      *string                      `json:"premiereAt,omitempty"`
+	// Premiere is set by GET /api/recordings/{id} while the premiere is
+	// playing.
+	Premiere *recordingPremiereResponse `json:"premiere,omitempty"`
+}
+
+type recordingChapterResponse struct {
+	OffsetSeconds int    `json:"offsetSeconds"`
+	Title         string `json:"title"`
 }
 
 type recordingRenditionResponse struct {
@@ -56,7 +71,7 @@ type clipExportSummaryResponse struct {
 
 type clipExportResponse struct {
 	ID           string  `json:"id"`
-	RecordingID  string  `json:"recordingId"`
+	RecordingID  string  `json:"recordingId,omitempty"`
 	ChannelID    string  `json:"channelId"`
 	SessionID    string  `json:"sessionId"`
 	Title        string  `json:"title"`
@@ -66,6 +81,7 @@ type clipExportResponse struct {
 	PlaybackURL  string  `json:"playbackUrl,omitempty"`
 	CreatedAt    string  `json:"createdAt"`
 	CompletedAt  *string `json:"completedAt,omitempty"`
+	CreatedBy    string  `json:"createdBy,omitempty"`
 }
 
 func newVodItemResponse(recording models.Recording) vodItemResponse {
@@ -74,6 +90,9 @@ func newVodItemResponse(recording models.Recording) vodItemResponse {
 		Title:           recording.Title,
 		DurationSeconds: recording.DurationSeconds,
 	}
+	if len(recording.Tags) > 0 {
+		item.Tags = append([]string(nil), recording.Tags...)
+	}
 	if recording.PublishedAt != nil {
 		publishedAt := recording.PublishedAt.Format(time.RFC3339Nano)
 		item.PublishedAt = &publishedAt
@@ -102,8 +121,14 @@ func newRecordingResponse(recording models.Recording) recordingResponse {
 		ChannelID:       recording.ChannelID,
 		SessionID:       recording.SessionID,
 		Title:           recording.Title,
+		Description:     recording.Description,
 		DurationSeconds: recording.DurationSeconds,
 		CreatedAt:       recording.CreatedAt.Format(time.RFC3339Nano),
+		SizeBytes:       recording.SizeBytes,
+		OverQuota:       recording.OverQuota,
+	}
+	if len(recording.Tags) > 0 {
+		resp.Tags = append([]string(nil), recording.Tags...)
 	}
 	if recording.PlaybackBaseURL != "" {
 		resp.PlaybackBaseURL = recording.PlaybackBaseURL
@@ -123,16 +148,12 @@ func newRecordingResponse(recording models.Recording) recordingResponse {
 		retain := recording.RetainUntil.Format(time.RFC3339Nano)
 		resp.RetainUntil = &retain
 	}
+	if recording.PremiereAt != nil {
+		premiereAt := recording.PremiereAt.Format(time.RFC3339Nano)
+		resp.PremiereAt = &premiereAt
+	}
 	if len(recording.Renditions) > 0 {
-		manifests := make([]recordingRenditionResponse, 0, len(recording.Renditions))
-		for _, rendition := range recording.Renditions {
-			manifests = append(manifests, recordingRenditionResponse{
-				Name:        rendition.Name,
-				ManifestURL: rendition.ManifestURL,
-				Bitrate:     rendition.Bitrate,
-			})
-		}
-		resp.Renditions = manifests
+		resp.Renditions = newRecordingRenditionResponses(recording.Renditions)
 	}
 	if len(recording.Thumbnails) > 0 {
 		thumbs := make([]recordingThumbnailResponse, 0, len(recording.Thumbnails))
@@ -160,6 +181,13 @@ func newRecordingResponse(recording models.Recording) recordingResponse {
 		}
 		resp.Clips = clips
 	}
+	if len(recording.Chapters) > 0 {
+		chapters := make([]recordingChapterResponse, 0, len(recording.Chapters))
+		for _, chapter := range recording.Chapters {
+			chapters = append(chapters, recordingChapterResponse(chapter))
+		}
+		resp.Chapters = chapters
+	}
 	return resp
 }
 
@@ -174,6 +202,7 @@ func newClipExportResponse(clip models.ClipExport) clipExportResponse {
 		EndSeconds:   clip.EndSeconds,
 		Status:       clip.Status,
 		CreatedAt:    clip.CreatedAt.Format(time.RFC3339Nano),
+		CreatedBy:    clip.CreatedBy,
 	}
 	if clip.PlaybackURL != "" {
 		resp.PlaybackURL = clip.PlaybackURL
@@ -198,26 +227,48 @@ func (h *Handler) Recordings(w http.ResponseWriter, r *http.Request) {
 	}
 
 	includeUnpublished := false
-	if actor, ok := UserFromContext(r.Context()); ok {
-		if channel, exists := h.Store.GetChannel(channelID); exists {
-			if channel.OwnerID == actor.ID || actor.HasRole(roleAdmin) {
-				includeUnpublished = true
-			}
+	actor, hasActor := UserFromContext(r.Context())
+	if channel, exists := h.Store.GetChannel(channelID); exists {
+		if hasActor && h.canManageChannelMedia(actor, channel) {
+			includeUnpublished = true
+		}
+		if reason := h.recordingAgeGate(channel, actor, hasActor); reason != "" {
+			WriteRequestError(w, ageGateError(reason))
+			return
 		}
 	}
 
 	recordings, err := h.Store.ListRecordings(channelID, includeUnpublished)
 	if err != nil {
-		WriteError(w, http.StatusBadRequest, err)
+		writeStorageError(w, http.StatusBadRequest, err)
 		return
 	}
+	tags := recordingTagFilter(r)
 	response := make([]recordingResponse, 0, len(recordings))
 	for _, recording := range recordings {
+		if !storage.RecordingHasTags(recording, tags) {
+			continue
+		}
 		response = append(response, newRecordingResponse(recording))
 	}
 	WriteJSON(w, http.StatusOK, response)
 }
 
+// recordingAgeGate applies ageGate to a mature channel's recordings, letting
+// anyone who manages the channel's media through.
+func (h *Handler) recordingAgeGate(channel models.Channel, actor models.User, hasActor bool) string {
+	if !channel.MatureContent {
+		return ""
+	}
+	if !hasActor {
+		return ageGate(channel, nil, h.now())
+	}
+	if h.canManageChannelMedia(actor, channel) {
+		return ""
+	}
+	return ageGate(channel, &actor, h.now())
+}
+
 func (h *Handler) RecordingByID(w http.ResponseWriter, r *http.Request) {
 	path := strings.TrimPrefix(r.URL.Path, "/api/recordings/")
 	if path == "" {
@@ -256,17 +307,31 @@ func (h *Handler) RecordingByID(w http.ResponseWriter, r *http.Request) {
 				WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
 				return
 			}
-			if channel.OwnerID != actor.ID && !actor.HasRole(roleAdmin) {
+			if !h.canManageChannelMedia(actor, channel) {
 				WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
 				return
 			}
+			if !h.requireScope(w, r, storage.APITokenScopeManageChannel) {
+				return
+			}
 			updated, err := h.Store.PublishRecording(recordingID)
 			if err != nil {
-				WriteError(w, http.StatusBadRequest, err)
+				if quotaErr, ok := storageQuotaError(err); ok {
+					WriteRequestError(w, quotaErr)
+					return
+				}
+				writeStorageError(w, http.StatusBadRequest, err)
 				return
 			}
 			WriteJSON(w, http.StatusOK, newRecordingResponse(updated))
 			return
+		case "report":
+			if len(remaining) > 1 {
+				WriteError(w, http.StatusNotFound, fmt.Errorf("unknown recording path"))
+				return
+			}
+			h.handleRecordingReport(recording, channel, w, r)
+			return
 		case "clips":
 			if len(remaining) > 1 {
 				WriteError(w, http.StatusNotFound, fmt.Errorf("unknown recording path"))
@@ -275,14 +340,18 @@ func (h *Handler) RecordingByID(w http.ResponseWriter, r *http.Request) {
 			switch r.Method {
 			case http.MethodGet:
 				if recording.PublishedAt == nil {
-					if !hasActor || (channel.OwnerID != actor.ID && !actor.HasRole(roleAdmin)) {
+					if !hasActor || !h.canManageChannelMedia(actor, channel) {
 						WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
 						return
 					}
 				}
+				if reason := h.recordingAgeGate(channel, actor, hasActor); reason != "" {
+					WriteRequestError(w, ageGateError(reason))
+					return
+				}
 				clips, err := h.Store.ListClipExports(recordingID)
 				if err != nil {
-					WriteError(w, http.StatusBadRequest, err)
+					writeStorageError(w, http.StatusBadRequest, err)
 					return
 				}
 				response := make([]clipExportResponse, 0, len(clips))
@@ -295,10 +364,13 @@ func (h *Handler) RecordingByID(w http.ResponseWriter, r *http.Request) {
 					WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
 					return
 				}
-				if channel.OwnerID != actor.ID && !actor.HasRole(roleAdmin) {
+				if !h.canManageChannelMedia(actor, channel) {
 					WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
 					return
 				}
+				if !h.requireScope(w, r, storage.APITokenScopeManageChannel) {
+					return
+				}
 				var req clipExportRequest
 				if !DecodeAndValidate(w, r, &req) {
 					return
@@ -314,7 +386,7 @@ func (h *Handler) RecordingByID(w http.ResponseWriter, r *http.Request) {
 					EndSeconds:   req.EndSeconds,
 				})
 				if err != nil {
-					WriteError(w, http.StatusBadRequest, err)
+					writeStorageError(w, http.StatusBadRequest, err)
 					return
 				}
 				WriteJSON(w, http.StatusCreated, newClipExportResponse(clip))
@@ -322,6 +394,33 @@ func (h *Handler) RecordingByID(w http.ResponseWriter, r *http.Request) {
 				WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodPost)
 			}
 			return
+		case "chapters":
+			if len(remaining) > 1 {
+				WriteError(w, http.StatusNotFound, fmt.Errorf("unknown recording path"))
+				return
+			}
+			h.setRecordingChapters(recording, channel, w, r)
+			return
+		case "playback":
+			h.handleRecordingPlayback(recording, channel, remaining[1:], w, r)
+			return
+		case "chat":
+			if len(remaining) > 1 {
+				WriteError(w, http.StatusNotFound, fmt.Errorf("unknown recording path"))
+				return
+			}
+			if r.Method != http.MethodGet {
+				WriteMethodNotAllowed(w, r, http.MethodGet)
+				return
+			}
+			if recording.PublishedAt == nil {
+				if !hasActor || !h.canManageChannelMedia(actor, channel) {
+					WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
+					return
+				}
+			}
+			h.recordingChatReplay(w, r, recording)
+			return
 		default:
 			WriteError(w, http.StatusNotFound, fmt.Errorf("unknown recording path"))
 			return
@@ -331,27 +430,46 @@ func (h *Handler) RecordingByID(w http.ResponseWriter, r *http.Request) {
 	switch r.Method {
 	case http.MethodGet:
 		if recording.PublishedAt == nil {
-			if !hasActor || (channel.OwnerID != actor.ID && !actor.HasRole(roleAdmin)) {
+			if !hasActor || !h.canManageChannelMedia(actor, channel) {
 				WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
 				return
 			}
 		}
-		WriteJSON(w, http.StatusOK, newRecordingResponse(recording))
+		if reason := h.recordingAgeGate(channel, actor, hasActor); reason != "" {
+			WriteRequestError(w, ageGateError(reason))
+			return
+		}
+		resp := newRecordingResponse(recording)
+		resp.Premiere = newRecordingPremiereResponse(recording, h.now())
+		if recording.SessionID != "" {
+			markers, err := h.Store.ListStreamMarkers(recording.SessionID)
+			if err != nil {
+				writeStorageError(w, http.StatusInternalServerError, err)
+				return
+			}
+			resp.Markers = recordingMarkers(recording, markers)
+		}
+		WriteJSON(w, http.StatusOK, resp)
 	case http.MethodDelete:
 		if !hasActor {
 			WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
 			return
 		}
-		if channel.OwnerID != actor.ID && !actor.HasRole(roleAdmin) {
+		if !h.canManageChannelMedia(actor, channel) {
 			WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
 			return
 		}
+		if !h.requireScope(w, r, storage.APITokenScopeManageChannel) {
+			return
+		}
 		if err := h.Store.DeleteRecording(recordingID); err != nil {
-			WriteError(w, http.StatusBadRequest, err)
+			writeStorageError(w, http.StatusBadRequest, err)
 			return
 		}
 		w.WriteHeader(http.StatusNoContent)
+	case http.MethodPatch:
+		h.updateRecording(recording, channel, w, r)
 	default:
-		WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodDelete)
+		WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodPatch, http.MethodDelete)
 	}
 }
@@ -0,0 +1,222 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+const (
+	defaultReportLimit  = 5
+	defaultReportWindow = 10 * time.Minute
+)
+
+// Actions a moderator can take on a report's subject while resolving it from
+// the moderation queue.
+const (
+	reportActionUnpublishRecording = "unpublish_recording"
+	reportActionStopStream         = "stop_stream"
+)
+
+// reportRequest is a viewer's report of a channel, its live stream, or one of
+// its recordings. Claimant is required for copyright reports.
+type reportRequest struct {
+	SubjectType string                 `json:"subjectType,omitempty"`
+	Category    string                 `json:"category"`
+	Reason      string                 `json:"reason"`
+	EvidenceURL string                 `json:"evidenceUrl,omitempty"`
+	Claimant    *models.ReportClaimant `json:"claimant,omitempty"`
+}
+
+// reportRate returns the configured reports-per-window limit, falling back
+// to the defaults for unset fields.
+func (h *Handler) reportRate() (int, time.Duration) {
+	limit := h.ReportLimit
+	if limit <= 0 {
+		limit = defaultReportLimit
+	}
+	window := h.ReportWindow
+	if window <= 0 {
+		window = defaultReportWindow
+	}
+	return limit, window
+}
+
+// reportFilterFromQuery reads the subjectType and category filters of a
+// report listing.
+func reportFilterFromQuery(r *http.Request) storage.ReportFilter {
+	query := r.URL.Query()
+	return storage.ReportFilter{
+		SubjectType: strings.ToLower(strings.TrimSpace(query.Get("subjectType"))),
+		Category:    strings.ToLower(strings.TrimSpace(query.Get("category"))),
+	}
+}
+
+func validateReportFilter(filter storage.ReportFilter) (RequestError, bool) {
+	if filter.SubjectType != "" && !storage.ValidReportSubjectType(filter.SubjectType) {
+		return ValidationError(fmt.Sprintf("unknown subjectType %s", filter.SubjectType)), false
+	}
+	if filter.Category != "" && !storage.ValidReportCategory(filter.Category) {
+		return ValidationError(fmt.Sprintf("unknown category %s", filter.Category)), false
+	}
+	return RequestError{}, true
+}
+
+// handleChannelReport lets a signed-in viewer report a channel, or with
+// subjectType stream_session the stream it is currently running.
+func (h *Handler) handleChannelReport(channel models.Channel, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+	viewer, ok := h.requireAuthenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	var req reportRequest
+	if !DecodeAndValidate(w, r, &req) {
+		return
+	}
+	subjectType := strings.ToLower(strings.TrimSpace(req.SubjectType))
+	subjectID := channel.ID
+	switch subjectType {
+	case "", models.ReportSubjectChannel:
+		subjectType = models.ReportSubjectChannel
+	case models.ReportSubjectStreamSession:
+		if channel.CurrentSessionID == nil || channel.LiveState != "live" {
+			WriteRequestError(w, channelOfflineError(channel, nil))
+			return
+		}
+		subjectID = *channel.CurrentSessionID
+	default:
+		WriteRequestError(w, ValidationError("subjectType must be channel or stream_session"))
+		return
+	}
+	h.fileReport(viewer, channel, subjectType, subjectID, req, w, r)
+}
+
+// handleRecordingReport lets a signed-in viewer report a recording they can
+// watch.
+func (h *Handler) handleRecordingReport(recording models.Recording, channel models.Channel, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		WriteMethodNotAllowed(w, r, http.MethodPost)
+		return
+	}
+	viewer, ok := h.requireAuthenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	if recording.PublishedAt == nil && !h.canManageChannelMedia(viewer, channel) {
+		WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
+		return
+	}
+	var req reportRequest
+	if !DecodeAndValidate(w, r, &req) {
+		return
+	}
+	if subjectType := strings.TrimSpace(req.SubjectType); subjectType != "" && subjectType != models.ReportSubjectRecording {
+		WriteRequestError(w, ValidationError("subjectType must be recording"))
+		return
+	}
+	h.fileReport(viewer, channel, models.ReportSubjectRecording, recording.ID, req, w, r)
+}
+
+// fileReport checks req, applies the reporter's rate limit, and stores the
+// report.
+func (h *Handler) fileReport(viewer models.User, channel models.Channel, subjectType, subjectID string, req reportRequest, w http.ResponseWriter, r *http.Request) {
+	category := strings.ToLower(strings.TrimSpace(req.Category))
+	if category == "" {
+		WriteRequestError(w, ValidationError("category is required"))
+		return
+	}
+	if !storage.ValidReportCategory(category) {
+		WriteRequestError(w, ValidationError("category must be one of illegal, explicit, harassment, copyright, or other"))
+		return
+	}
+	if strings.TrimSpace(req.Reason) == "" {
+		WriteRequestError(w, ValidationError("reason is required"))
+		return
+	}
+
+	limit, window := h.reportRate()
+	if allowed, retry := h.reports.allow(viewer.ID, limit, window, h.now()); !allowed {
+		seconds := int((retry + time.Second - 1) / time.Second)
+		if seconds < 1 {
+			seconds = 1
+		}
+		w.Header().Set("Retry-After", strconv.Itoa(seconds))
+		WriteRequestError(w, RequestError{Status: http.StatusTooManyRequests, CodeVal: "rate_limited", Message: fmt.Sprintf("you can file %d reports every %s", limit, window)})
+		return
+	}
+
+	report, err := h.Store.CreateReport(storage.ReportParams{
+		ChannelID:   channel.ID,
+		ReporterID:  viewer.ID,
+		SubjectType: subjectType,
+		SubjectID:   subjectID,
+		Category:    category,
+		Reason:      req.Reason,
+		EvidenceURL: req.EvidenceURL,
+		Claimant:    req.Claimant,
+	})
+	if err != nil {
+		writeStorageError(w, http.StatusBadRequest, err)
+		return
+	}
+	WriteJSON(w, http.StatusAccepted, newChatReportResponse(report))
+}
+
+// applyReportAction takes action against the subject of report before it is
+// resolved, reusing the operations behind unpublishing recordings and
+// stopping streams.
+func (h *Handler) applyReportAction(r *http.Request, actor models.User, report models.ChatReport, action string) error {
+	switch action {
+	case reportActionUnpublishRecording:
+		if report.SubjectType != models.ReportSubjectRecording {
+			return ValidationError(fmt.Sprintf("%s applies only to recording reports", action))
+		}
+		if _, err := h.Store.UnpublishRecording(report.SubjectID); err != nil {
+			return RequestError{Status: http.StatusBadRequest, CodeVal: "validation_failed", Message: err.Error(), Err: err}
+		}
+		h.auditLogger().Info("audit", "action", "report.unpublish_recording", "user_id", actor.ID, "report_id", report.ID, "channel_id", report.ChannelID, "recording_id", report.SubjectID)
+		return nil
+	case reportActionStopStream:
+		if report.SubjectType != models.ReportSubjectStreamSession && report.SubjectType != models.ReportSubjectChannel {
+			return ValidationError(fmt.Sprintf("%s applies only to stream and channel reports", action))
+		}
+		channel, ok := h.Store.GetChannel(report.ChannelID)
+		if !ok {
+			return RequestError{Status: http.StatusNotFound, CodeVal: "not_found", Message: fmt.Sprintf("channel %s not found", report.ChannelID)}
+		}
+		if channel.LiveState != "live" || channel.CurrentSessionID == nil {
+			return channelOfflineError(channel, nil)
+		}
+		if report.SubjectType == models.ReportSubjectStreamSession && *channel.CurrentSessionID != report.SubjectID {
+			return RequestError{Status: http.StatusConflict, CodeVal: "session_ended", Message: fmt.Sprintf("stream session %s has already ended", report.SubjectID)}
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), h.streamRequestBudget())
+		defer cancel()
+		if _, err := h.Store.StopStreamContext(ctx, channel.ID, h.srsTracker().peak(channel.ID)); err != nil {
+			if reqErr, ok := streamTimeout(err); ok {
+				return reqErr
+			}
+			if errors.Is(err, storage.ErrIngestControllerUnavailable) {
+				return RequestError{Status: http.StatusServiceUnavailable, CodeVal: "service_unavailable", Message: err.Error(), Err: err}
+			}
+			return RequestError{Status: http.StatusBadRequest, CodeVal: "validation_failed", Message: err.Error(), Err: err}
+		}
+		h.srsTracker().clear(channel.ID)
+		h.invalidateChannelCache(r.Context(), channel.ID)
+		h.auditLogger().Info("audit", "action", "report.stop_stream", "user_id", actor.ID, "report_id", report.ID, "channel_id", channel.ID, "session_id", *channel.CurrentSessionID)
+		return nil
+	default:
+		return ValidationError(fmt.Sprintf("action must be %s or %s", reportActionUnpublishRecording, reportActionStopStream))
+	}
+}
@@ -0,0 +1,206 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+func TestReportsCoverChannelsStreamsAndRecordings(t *testing.T) {
+	handler, store := newTestHandler(t)
+	admin, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Admin", Email: "admin@example.com", Roles: []string{"admin"}})
+	if err != nil {
+		t.Fatalf("CreateUser admin: %v", err)
+	}
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser owner: %v", err)
+	}
+	viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser viewer: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Arena", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	if _, err := store.StartStream(channel.ID, []string{"720p"}); err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	if _, err := store.StopStream(channel.ID, 10); err != nil {
+		t.Fatalf("StopStream: %v", err)
+	}
+	recordings, err := store.ListRecordings(channel.ID, true)
+	if err != nil || len(recordings) == 0 {
+		t.Fatalf("expected a recording, got %v (%v)", recordings, err)
+	}
+	recording := recordings[0]
+
+	reportChannel := func(user models.User, body string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/report", strings.NewReader(body)), user)
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		return rec
+	}
+	reportRecording := func(user models.User, body string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(http.MethodPost, "/api/recordings/"+recording.ID+"/report", strings.NewReader(body)), user)
+		rec := httptest.NewRecorder()
+		handler.RecordingByID(rec, req)
+		return rec
+	}
+
+	if rec := reportRecording(viewer, `{"category":"explicit","reason":"nsfw"}`); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 reporting an unpublished recording, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, err := store.PublishRecording(recording.ID); err != nil {
+		t.Fatalf("PublishRecording: %v", err)
+	}
+	if rec := reportRecording(viewer, `{"category":"copyright","reason":"my song"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a copyright report without a claimant, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec := reportRecording(viewer, `{"category":"copyright","reason":"my song","claimant":{"name":"Label","email":"legal@label.example"}}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202 reporting a recording, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var recordingReport chatReportResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &recordingReport); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	if recordingReport.SubjectType != models.ReportSubjectRecording || recordingReport.SubjectID != recording.ID || recordingReport.Claimant == nil {
+		t.Fatalf("unexpected recording report: %s", rec.Body.String())
+	}
+
+	if rec := reportChannel(viewer, `{"subjectType":"stream_session","category":"illegal","reason":"live now"}`); rec.Code != http.StatusConflict {
+		t.Fatalf("expected 409 reporting an offline stream, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := reportChannel(viewer, `{"category":"spam","reason":"bots"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown category, got %d: %s", rec.Code, rec.Body.String())
+	}
+	session, err := store.StartStream(channel.ID, []string{"720p"})
+	if err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	rec = reportChannel(viewer, `{"subjectType":"stream_session","category":"illegal","reason":"live now"}`)
+	if rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202 reporting the stream, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var streamReport chatReportResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &streamReport); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	if streamReport.SubjectID != session.ID {
+		t.Fatalf("expected the report on session %s, got %s", session.ID, streamReport.SubjectID)
+	}
+	if rec := reportChannel(viewer, `{"category":"harassment","reason":"targets viewers"}`); rec.Code != http.StatusAccepted {
+		t.Fatalf("expected 202 reporting the channel, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	queue := func(query string) moderationQueueResponse {
+		t.Helper()
+		req := withUser(httptest.NewRequest(http.MethodGet, "/api/moderation/queue"+query, nil), admin)
+		rec := httptest.NewRecorder()
+		handler.ModerationQueue(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected queue status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp moderationQueueResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode queue: %v", err)
+		}
+		return resp
+	}
+	if got := queue(""); len(got.Queue) != 3 {
+		t.Fatalf("expected three queued reports, got %d", len(got.Queue))
+	}
+	if got := queue("?subjectType=recording"); len(got.Queue) != 1 || got.Queue[0].ID != recordingReport.ID {
+		t.Fatalf("expected only the recording report, got %+v", got.Queue)
+	}
+	if got := queue("?category=illegal"); len(got.Queue) != 1 || got.Queue[0].ID != streamReport.ID {
+		t.Fatalf("expected only the illegal-content report, got %+v", got.Queue)
+	}
+	req := withUser(httptest.NewRequest(http.MethodGet, "/api/moderation/queue?subjectType=profile", nil), admin)
+	rec = httptest.NewRecorder()
+	handler.ModerationQueue(rec, req)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown subject filter, got %d", rec.Code)
+	}
+
+	resolve := func(id, body string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(http.MethodPost, "/api/moderation/queue/"+id, strings.NewReader(body)), admin)
+		rec := httptest.NewRecorder()
+		handler.ModerationQueueByID(rec, req)
+		return rec
+	}
+	if rec := resolve(recordingReport.ID, `{"resolution":"removed","action":"stop_stream"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 stopping a stream from a recording report, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := resolve(recordingReport.ID, `{"resolution":"removed","action":"unpublish_recording"}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 unpublishing, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if updated, ok := store.GetRecording(recording.ID); !ok || updated.PublishedAt != nil {
+		t.Fatalf("expected the recording to be unpublished, got %+v", updated)
+	}
+	handler.srsTracker().increment(channel.ID)
+	if rec := resolve(streamReport.ID, `{"resolution":"stopped","action":"stop_stream"}`); rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 stopping the stream, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if updated, ok := store.GetChannel(channel.ID); !ok || updated.LiveState == "live" {
+		t.Fatalf("expected the channel to be offline, got %+v", updated)
+	}
+	if peak := handler.srsTracker().peak(channel.ID); peak != 0 {
+		t.Fatalf("expected stopping the stream to drop the tracked peak, got %d", peak)
+	}
+	if got := queue(""); len(got.Queue) != 1 || got.Queue[0].SubjectType != models.ReportSubjectChannel {
+		t.Fatalf("expected only the channel report left, got %+v", got.Queue)
+	}
+}
+
+func TestReportsAreRateLimitedPerReporter(t *testing.T) {
+	handler, store := newTestHandler(t)
+	handler.ReportLimit = 2
+	handler.ReportWindow = time.Minute
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser owner: %v", err)
+	}
+	viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser viewer: %v", err)
+	}
+	other, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Other", Email: "other@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser other: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Arena", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	report := func(user models.User) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/report", strings.NewReader(`{"category":"other","reason":"spam"}`)), user)
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		return rec
+	}
+
+	for i := 0; i < 2; i++ {
+		if rec := report(viewer); rec.Code != http.StatusAccepted {
+			t.Fatalf("report %d: expected 202, got %d: %s", i, rec.Code, rec.Body.String())
+		}
+	}
+	rec := report(viewer)
+	if rec.Code != http.StatusTooManyRequests || decodeAPIError(t, rec.Body.Bytes()).Error.Code != "rate_limited" {
+		t.Fatalf("expected 429 rate_limited, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Fatal("expected a Retry-After header")
+	}
+	if rec := report(other); rec.Code != http.StatusAccepted {
+		t.Fatalf("expected other reporters to be unaffected, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,123 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"flag"
+	"net/http"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/chat"
+	"bitriver-live/internal/storage"
+)
+
+var updateGolden = flag.Bool("update", false, "rewrite the response golden files under testdata")
+
+// goldenTimestamp matches the RFC 3339 timestamps responses carry, which
+// differ on every run.
+var goldenTimestamp = regexp.MustCompile(`"\d{4}-\d{2}-\d{2}T\d{2}:\d{2}:\d{2}(\.\d+)?(Z|[+-]\d{2}:\d{2})"`)
+
+// TestListResponsesMatchGoldenFiles locks the shape of representative list
+// responses for a seeded dataset. Run with -update after an intended change
+// to a response and review the diff of testdata/responses.
+func TestListResponsesMatchGoldenFiles(t *testing.T) {
+	handler, store := newTestHandler(t)
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Creator", Email: "creator@example.com", Roles: []string{"admin", "creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser owner: %v", err)
+	}
+	viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser viewer: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Workshop", "maker", []string{"cnc", "wood"})
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	bio := "Builds things."
+	topFriends := []string{owner.ID}
+	if _, err := store.UpsertProfile(viewer.ID, storage.ProfileUpdate{Bio: &bio, TopFriends: &topFriends}); err != nil {
+		t.Fatalf("UpsertProfile: %v", err)
+	}
+	if err := store.FollowChannel(viewer.ID, channel.ID); err != nil {
+		t.Fatalf("FollowChannel: %v", err)
+	}
+	if _, err := store.CreateChatMessage(channel.ID, viewer.ID, "hello", ""); err != nil {
+		t.Fatalf("CreateChatMessage: %v", err)
+	}
+	if err := store.ApplyChatEvent(chat.Event{
+		Type:       chat.EventTypeModeration,
+		Moderation: &chat.ModerationEvent{Action: chat.ModerationActionBan, ChannelID: channel.ID, ActorID: owner.ID, TargetID: viewer.ID, Reason: "spam"},
+		OccurredAt: time.Now().UTC(),
+	}); err != nil {
+		t.Fatalf("ApplyChatEvent: %v", err)
+	}
+	handler.ChatGateway = chat.NewGateway(chat.GatewayConfig{Queue: chat.NewMemoryQueue(16), Store: store})
+
+	replacer := strings.NewReplacer(owner.ID, "<owner-id>", viewer.ID, "<viewer-id>", channel.ID, "<channel-id>")
+	mux := newShapeMux(handler)
+	cases := []struct {
+		name string
+		path string
+	}{
+		{"users", "/api/users"},
+		{"channels", "/api/channels"},
+		{"directory", "/api/directory"},
+		{"profiles", "/api/profiles"},
+		{"chat", "/api/channels/" + channel.ID + "/chat"},
+		{"chat_restrictions", "/api/channels/" + channel.ID + "/chat/moderation/restrictions"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			code, body := getShape(t, mux, owner, tc.path)
+			if code != http.StatusOK {
+				t.Fatalf("expected 200, got %d: %s", code, body)
+			}
+			var indented bytes.Buffer
+			if err := json.Indent(&indented, body, "", "  "); err != nil {
+				t.Fatalf("indent: %v", err)
+			}
+			got := goldenTimestamp.ReplaceAllString(replacer.Replace(indented.String()), `"<time>"`)
+			got = scrubGenerated(got)
+			compareGolden(t, filepath.Join("testdata", "responses", tc.name+".golden.json"), got)
+		})
+	}
+}
+
+var (
+	// goldenGeneratedID matches the hex IDs the store generates for records
+	// the test does not hold, such as chat messages.
+	goldenGeneratedID = regexp.MustCompile(`"[0-9a-f]{32}"`)
+	// goldenStreamKeyHint matches the hint derived from a random stream key.
+	goldenStreamKeyHint = regexp.MustCompile(`"streamKeyHint": "[^"]*"`)
+)
+
+func scrubGenerated(body string) string {
+	body = goldenStreamKeyHint.ReplaceAllString(body, `"streamKeyHint": "<hint>"`)
+	return goldenGeneratedID.ReplaceAllString(body, `"<id>"`)
+}
+
+func compareGolden(t *testing.T, path, got string) {
+	t.Helper()
+	if *updateGolden {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			t.Fatalf("create golden dir: %v", err)
+		}
+		if err := os.WriteFile(path, []byte(got), 0o644); err != nil {
+			t.Fatalf("write golden: %v", err)
+		}
+		return
+	}
+	want, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read golden (run with -update to create it): %v", err)
+	}
+	if string(want) != got {
+		t.Fatalf("response does not match %s (run with -update to accept)\n got: %s\nwant: %s", path, got, want)
+	}
+}
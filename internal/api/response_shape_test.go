@@ -0,0 +1,322 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/chat"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+// testPage mirrors pageResponse with a typed item list so shape checks know
+// what the items are.
+type testPage[T any] struct {
+	Items   []T `json:"items"`
+	Total   int `json:"total"`
+	Page    int `json:"page"`
+	PerPage int `json:"perPage"`
+}
+
+// listEndpoint is a GET endpoint returning collections and the Go type its
+// body is declared as.
+type listEndpoint struct {
+	path  string
+	shape reflect.Type
+}
+
+func shapeOf[T any]() reflect.Type {
+	return reflect.TypeOf((*T)(nil)).Elem()
+}
+
+// listEndpoints covers every API endpoint that lists resources. channelID is
+// a channel owned by the caller, and recordingID one of its recordings.
+func listEndpoints(channelID, recordingID string) []listEndpoint {
+	channel := "/api/channels/" + channelID
+	return []listEndpoint{
+		{"/api/users", shapeOf[[]userResponse]()},
+		{"/api/users?page=1", shapeOf[testPage[userResponse]]()},
+		{"/api/users/me/tokens", shapeOf[[]apiTokenResponse]()},
+		{"/api/users/me/recommendations", shapeOf[recommendationsResponse]()},
+		{"/api/directory", shapeOf[directoryResponse]()},
+		{"/api/directory?q=nothing", shapeOf[directoryResponse]()},
+		{"/api/directory/featured", shapeOf[directoryResponse]()},
+		{"/api/directory/recommended", shapeOf[directoryResponse]()},
+		{"/api/directory/following", shapeOf[directoryResponse]()},
+		{"/api/directory/live", shapeOf[directoryResponse]()},
+		{"/api/directory/trending", shapeOf[directoryResponse]()},
+		{"/api/directory/categories", shapeOf[categoryDirectoryResponse]()},
+		{"/api/channels", shapeOf[[]channelResponse]()},
+		{"/api/profiles", shapeOf[[]profileViewResponse]()},
+		{"/api/profiles?page=1", shapeOf[testPage[profileViewResponse]]()},
+		{"/api/recordings?channelId=" + channelID, shapeOf[[]recordingResponse]()},
+		{"/api/recordings/" + recordingID + "/clips", shapeOf[[]clipExportResponse]()},
+		{"/api/uploads?channelId=" + channelID, shapeOf[[]uploadResponse]()},
+		{"/api/moderation/queue", shapeOf[moderationQueueResponse]()},
+		{"/api/analytics/overview", shapeOf[analyticsOverviewResponse]()},
+		{"/api/admin/health/components", shapeOf[componentHealthResponse]()},
+		{"/api/badges", shapeOf[[]badgeDefinitionResponse]()},
+		{"/api/admin/badges/staff/users", shapeOf[[]badgeGrantResponse]()},
+		{channel + "/sessions", shapeOf[[]sessionResponse]()},
+		{channel + "/followers", shapeOf[testPage[channelFollowerResponse]]()},
+		{channel + "/vods", shapeOf[vodCollectionResponse]()},
+		{channel + "/editors", shapeOf[[]channelEditorResponse]()},
+		{channel + "/moderators", shapeOf[[]channelModeratorResponse]()},
+		{channel + "/restreams", shapeOf[[]restreamTargetResponse]()},
+		{channel + "/stream/markers", shapeOf[[]streamMarkerResponse]()},
+		{channel + "/chat", shapeOf[[]chatMessageResponse]()},
+		{channel + "/chat/moderation/restrictions", shapeOf[[]chatRestrictionResponse]()},
+		{channel + "/chat/reports", shapeOf[[]chatReportResponse]()},
+		{channel + "/chat/appeals", shapeOf[[]chatAppealResponse]()},
+		{channel + "/monetization/tips", shapeOf[[]tipResponse]()},
+		{channel + "/monetization/subscriptions", shapeOf[[]subscriptionResponse]()},
+	}
+}
+
+// newShapeMux routes the endpoints in listEndpoints the way the server does.
+func newShapeMux(handler *Handler) *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/users", handler.Users)
+	mux.HandleFunc("/api/users/", handler.UserByID)
+	mux.HandleFunc("/api/directory", handler.Directory)
+	mux.HandleFunc("/api/directory/featured", handler.DirectoryFeatured)
+	mux.HandleFunc("/api/directory/recommended", handler.DirectoryRecommended)
+	mux.HandleFunc("/api/directory/following", handler.DirectoryFollowing)
+	mux.HandleFunc("/api/directory/live", handler.DirectoryLive)
+	mux.HandleFunc("/api/directory/trending", handler.DirectoryTrending)
+	mux.HandleFunc("/api/directory/categories", handler.DirectoryCategories)
+	mux.HandleFunc("/api/channels", handler.Channels)
+	mux.HandleFunc("/api/channels/", handler.ChannelByID)
+	mux.HandleFunc("/api/profiles", handler.Profiles)
+	mux.HandleFunc("/api/profiles/", handler.ProfileByID)
+	mux.HandleFunc("/api/recordings", handler.Recordings)
+	mux.HandleFunc("/api/recordings/", handler.RecordingByID)
+	mux.HandleFunc("/api/uploads", handler.Uploads)
+	mux.HandleFunc("/api/moderation/queue", handler.ModerationQueue)
+	mux.HandleFunc("/api/analytics/overview", handler.AnalyticsOverview)
+	mux.HandleFunc("/api/admin/health/components", handler.AdminComponentHealth)
+	mux.HandleFunc("/api/badges", handler.Badges)
+	mux.HandleFunc("/api/admin/badges", handler.AdminBadges)
+	mux.HandleFunc("/api/admin/badges/", handler.AdminBadgeBySlug)
+	return mux
+}
+
+// newShapeFixture returns a handler over a datastore holding only an admin
+// and their live channel with one earlier recording, the least every list
+// endpoint needs to be reachable.
+func newShapeFixture(t *testing.T) (*Handler, *storage.Storage, models.User, models.Channel, models.Recording) {
+	t.Helper()
+	handler, store := newTestHandler(t)
+	admin, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Admin", Email: "admin@example.com", Roles: []string{"admin", "creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(admin.ID, "Quiet", "", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	if _, err := store.StartStream(channel.ID, []string{"720p"}); err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	if _, err := store.StopStream(channel.ID, 0); err != nil {
+		t.Fatalf("StopStream: %v", err)
+	}
+	recordings, err := store.ListRecordings(channel.ID, true)
+	if err != nil || len(recordings) != 1 {
+		t.Fatalf("expected one recording, got %d (%v)", len(recordings), err)
+	}
+	// Going live again lets the session-scoped endpoints answer.
+	if _, err := store.StartStream(channel.ID, []string{"720p"}); err != nil {
+		t.Fatalf("StartStream again: %v", err)
+	}
+	channel, _ = store.GetChannel(channel.ID)
+	queue := chat.NewMemoryQueue(16)
+	handler.ChatGateway = chat.NewGateway(chat.GatewayConfig{Queue: queue, Store: store})
+	return handler, store, admin, channel, recordings[0]
+}
+
+// getShape fetches path as user and decodes its body.
+func getShape(t *testing.T, mux http.Handler, user models.User, path string) (int, []byte) {
+	t.Helper()
+	req := withUser(httptest.NewRequest(http.MethodGet, path, nil), user)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec.Code, rec.Body.Bytes()
+}
+
+// assertNoNullCollections fails for every null in body where shape declares
+// a slice or map.
+func assertNoNullCollections(t *testing.T, path string, body []byte, shape reflect.Type) {
+	t.Helper()
+	var decoded interface{}
+	if err := json.Unmarshal(body, &decoded); err != nil {
+		t.Fatalf("%s: decode: %v", path, err)
+	}
+	for _, problem := range nullCollections("$", decoded, shape) {
+		t.Errorf("%s: %s", path, problem)
+	}
+}
+
+func nullCollections(at string, value interface{}, shape reflect.Type) []string {
+	for shape.Kind() == reflect.Pointer {
+		if value == nil {
+			return nil
+		}
+		shape = shape.Elem()
+	}
+	if encodesItself(shape) || shape.Kind() == reflect.Interface {
+		return nil
+	}
+	switch shape.Kind() {
+	case reflect.Slice, reflect.Array:
+		items, ok := value.([]interface{})
+		if !ok {
+			return []string{at + " is " + describeJSON(value) + ", want an array"}
+		}
+		var problems []string
+		for i, item := range items {
+			problems = append(problems, nullCollections(at+"["+strconv.Itoa(i)+"]", item, shape.Elem())...)
+		}
+		return problems
+	case reflect.Map:
+		entries, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{at + " is " + describeJSON(value) + ", want an object"}
+		}
+		var problems []string
+		for key, entry := range entries {
+			problems = append(problems, nullCollections(at+"."+key, entry, shape.Elem())...)
+		}
+		return problems
+	case reflect.Struct:
+		fields, ok := value.(map[string]interface{})
+		if !ok {
+			return []string{at + " is " + describeJSON(value) + ", want an object"}
+		}
+		return nullFieldCollections(at, fields, shape)
+	}
+	return nil
+}
+
+func nullFieldCollections(at string, fields map[string]interface{}, shape reflect.Type) []string {
+	var problems []string
+	for i := 0; i < shape.NumField(); i++ {
+		field := shape.Field(i)
+		name, opts, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			continue
+		}
+		if field.Anonymous && name == "" {
+			embedded := field.Type
+			if embedded.Kind() == reflect.Pointer {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				problems = append(problems, nullFieldCollections(at, fields, embedded)...)
+				continue
+			}
+		}
+		if !field.IsExported() {
+			continue
+		}
+		if name == "" {
+			name = field.Name
+		}
+		value, present := fields[name]
+		if !present {
+			if !strings.Contains(opts, "omitempty") {
+				problems = append(problems, at+"."+name+" is missing")
+			}
+			continue
+		}
+		problems = append(problems, nullCollections(at+"."+name, value, field.Type)...)
+	}
+	return problems
+}
+
+func describeJSON(value interface{}) string {
+	if value == nil {
+		return "null"
+	}
+	return reflect.TypeOf(value).String()
+}
+
+func TestListEndpointsReturnEmptyCollectionsForEmptyDatastore(t *testing.T) {
+	handler, _, admin, channel, recording := newShapeFixture(t)
+	mux := newShapeMux(handler)
+	for _, endpoint := range listEndpoints(channel.ID, recording.ID) {
+		code, body := getShape(t, mux, admin, endpoint.path)
+		if code != http.StatusOK {
+			t.Errorf("%s: expected 200, got %d: %s", endpoint.path, code, body)
+			continue
+		}
+		assertNoNullCollections(t, endpoint.path, body, endpoint.shape)
+	}
+}
+
+// TestListResponseTypesEncodeZeroValuesWithoutNulls writes the zero value of
+// every declared list response, which is what a repository returning nil
+// slices produces, and checks no collection comes out as null.
+func TestListResponseTypesEncodeZeroValuesWithoutNulls(t *testing.T) {
+	for _, endpoint := range listEndpoints("channel", "recording") {
+		rec := httptest.NewRecorder()
+		WriteJSON(rec, http.StatusOK, reflect.Zero(endpoint.shape).Interface())
+		assertNoNullCollections(t, endpoint.shape.String(), rec.Body.Bytes(), endpoint.shape)
+
+		item := endpoint.shape
+		if item.Kind() == reflect.Slice {
+			item = item.Elem()
+			rec := httptest.NewRecorder()
+			WriteJSON(rec, http.StatusOK, reflect.MakeSlice(endpoint.shape, 1, 1).Interface())
+			assertNoNullCollections(t, item.String(), rec.Body.Bytes(), endpoint.shape)
+		}
+	}
+}
+
+func TestEmptyCollectionsFillsNilSlicesAndMaps(t *testing.T) {
+	type inner struct {
+		Tags []string `json:"tags"`
+	}
+	type hidden struct {
+		Promoted []int `json:"promoted"`
+	}
+	type payload struct {
+		hidden
+		Items    []inner           `json:"items"`
+		Labels   map[string]string `json:"labels"`
+		Nested   *inner            `json:"nested"`
+		Missing  *inner            `json:"missing"`
+		Omitted  []string          `json:"omitted,omitempty"`
+		Raw      json.RawMessage   `json:"raw,omitempty"`
+		Bytes    []byte            `json:"bytes"`
+		When     time.Time         `json:"when"`
+		Any      interface{}       `json:"any"`
+		Skipped  []string          `json:"-"`
+		internal []string
+	}
+	original := payload{Items: []inner{{}}, Nested: &inner{}, Any: []string(nil)}
+
+	body, err := marshalResponse(original)
+	if err != nil {
+		t.Fatalf("marshalResponse: %v", err)
+	}
+	want := `{"promoted":[],"items":[{"tags":[]}],"labels":{},"nested":{"tags":[]},"missing":null,"bytes":null,"when":"0001-01-01T00:00:00Z","any":[]}`
+	if string(body) != want {
+		t.Fatalf("unexpected encoding\n got: %s\nwant: %s", body, want)
+	}
+	if original.Items[0].Tags != nil || original.Nested.Tags != nil || original.Labels != nil {
+		t.Fatal("expected the payload to be left unmodified")
+	}
+	if got := emptyCollections(nil); got != nil {
+		t.Fatalf("expected nil to stay nil, got %#v", got)
+	}
+	if got := emptyCollections([]string(nil)); !reflect.DeepEqual(got, []string{}) {
+		t.Fatalf("expected a top-level nil slice to become empty, got %#v", got)
+	}
+}
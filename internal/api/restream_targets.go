@@ -0,0 +1,184 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+type restreamTargetRequest struct {
+	Label     string `json:"label"`
+	URL       string `json:"url"`
+	StreamKey string `json:"streamKey"`
+	Enabled   *bool  `json:"enabled"`
+}
+
+type restreamTargetUpdateRequest struct {
+	Label     *string `json:"label"`
+	URL       *string `json:"url"`
+	StreamKey *string `json:"streamKey"`
+	Enabled   *bool   `json:"enabled"`
+}
+
+// restreamTargetResponse never carries the stream key: it is write-only and
+// can only be replaced.
+type restreamTargetResponse struct {
+	ID        string `json:"id"`
+	Label     string `json:"label"`
+	URL       string `json:"url"`
+	Enabled   bool   `json:"enabled"`
+	CreatedAt string `json:"createdAt"`
+	UpdatedAt string `json:"updatedAt"`
+}
+
+type restreamStatusResponse struct {
+	TargetID  string `json:"targetId"`
+	Label     string `json:"label,omitempty"`
+	State     string `json:"state"`
+	Restarts  int    `json:"restarts"`
+	LastError string `json:"lastError,omitempty"`
+}
+
+func newRestreamTargetResponse(target models.RestreamTarget) restreamTargetResponse {
+	return restreamTargetResponse{
+		ID:        target.ID,
+		Label:     target.Label,
+		URL:       target.URL,
+		Enabled:   target.Enabled,
+		CreatedAt: target.CreatedAt.Format(time.RFC3339Nano),
+		UpdatedAt: target.UpdatedAt.Format(time.RFC3339Nano),
+	}
+}
+
+// restreamTargetError maps storage errors from the restream target methods.
+func restreamTargetError(err error) RequestError {
+	switch {
+	case errors.Is(err, storage.ErrRestreamTargetNotFound):
+		return RequestError{Status: http.StatusNotFound, Message: "restream target not found", Err: err}
+	case errors.Is(err, storage.ErrRestreamTargetLimit):
+		return RequestError{Status: http.StatusConflict, CodeVal: "restream_target_limit", Message: fmt.Sprintf("a channel can have at most %d restream targets", storage.MaxRestreamTargetsPerChannel), Err: err}
+	case errors.Is(err, storage.ErrSecretKeyUnavailable):
+		return RequestError{Status: http.StatusNotImplemented, CodeVal: "secret_key_unconfigured", Message: "restreaming requires a server secret key", Err: err}
+	default:
+		return RequestError{Status: http.StatusBadRequest, Err: err}
+	}
+}
+
+// handleRestreamTargets serves /api/channels/{id}/restreams and
+// /api/channels/{id}/restreams/{targetId}. Channel managers list and add
+// targets, and change (PATCH) or remove (DELETE) one. Changes apply from the
+// channel's next stream.
+func (h *Handler) handleRestreamTargets(channel models.Channel, remaining []string, w http.ResponseWriter, r *http.Request) {
+	if _, ok := h.ensureChannelAccess(w, r, channel); !ok {
+		return
+	}
+	if len(remaining) > 1 {
+		WriteError(w, http.StatusNotFound, fmt.Errorf("unknown restreams path"))
+		return
+	}
+
+	if len(remaining) == 1 && strings.TrimSpace(remaining[0]) != "" {
+		targetID := strings.TrimSpace(remaining[0])
+		switch r.Method {
+		case http.MethodPatch:
+			var req restreamTargetUpdateRequest
+			if !DecodeAndValidate(w, r, &req) {
+				return
+			}
+			target, err := h.Store.UpdateRestreamTarget(channel.ID, targetID, storage.RestreamTargetUpdate{
+				Label:     req.Label,
+				URL:       req.URL,
+				StreamKey: req.StreamKey,
+				Enabled:   req.Enabled,
+			})
+			if err != nil {
+				WriteRequestError(w, restreamTargetError(err))
+				return
+			}
+			WriteJSON(w, http.StatusOK, newRestreamTargetResponse(target))
+		case http.MethodDelete:
+			if err := h.Store.DeleteRestreamTarget(channel.ID, targetID); err != nil {
+				WriteRequestError(w, restreamTargetError(err))
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			WriteMethodNotAllowed(w, r, http.MethodPatch, http.MethodDelete)
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		targets, err := h.Store.ListRestreamTargets(channel.ID)
+		if err != nil {
+			writeStorageError(w, http.StatusInternalServerError, err)
+			return
+		}
+		response := make([]restreamTargetResponse, 0, len(targets))
+		for _, target := range targets {
+			response = append(response, newRestreamTargetResponse(target))
+		}
+		WriteJSON(w, http.StatusOK, response)
+	case http.MethodPost:
+		var req restreamTargetRequest
+		if !DecodeAndValidate(w, r, &req) {
+			return
+		}
+		enabled := true
+		if req.Enabled != nil {
+			enabled = *req.Enabled
+		}
+		target, err := h.Store.CreateRestreamTarget(channel.ID, storage.RestreamTargetParams{
+			Label:     req.Label,
+			URL:       req.URL,
+			StreamKey: req.StreamKey,
+			Enabled:   enabled,
+		})
+		if err != nil {
+			WriteRequestError(w, restreamTargetError(err))
+			return
+		}
+		WriteJSON(w, http.StatusCreated, newRestreamTargetResponse(target))
+	default:
+		WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodPost)
+	}
+}
+
+// writeRestreamStatus serves GET /api/channels/{id}/stream/restreams with
+// the state of each restream of the live session. It answers 409 while the
+// channel is offline.
+func (h *Handler) writeRestreamStatus(channel models.Channel, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	statuses, err := h.Store.RestreamStatus(channel.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrChannelNotLive):
+			WriteRequestError(w, channelOfflineError(channel, err))
+		case errors.Is(err, storage.ErrIngestControllerUnavailable):
+			WriteError(w, http.StatusServiceUnavailable, err)
+		default:
+			WriteError(w, http.StatusBadGateway, err)
+		}
+		return
+	}
+	response := make([]restreamStatusResponse, 0, len(statuses))
+	for _, status := range statuses {
+		response = append(response, restreamStatusResponse{
+			TargetID:  status.TargetID,
+			Label:     status.Target.Label,
+			State:     status.State,
+			Restarts:  status.Restarts,
+			LastError: status.LastError,
+		})
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,133 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/auth"
+	"bitriver-live/internal/ingest"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+// restreamController reports a fixed restream status for every channel.
+type restreamController struct {
+	bootResultController
+	statuses []ingest.RestreamStatus
+}
+
+func (c restreamController) RestreamStatus(ctx context.Context, channelID string) ([]ingest.RestreamStatus, error) {
+	return c.statuses, nil
+}
+
+func TestRestreamTargetsKeepStreamKeysWriteOnly(t *testing.T) {
+	controller := restreamController{bootResultController: bootResultController{result: ingest.BootResult{JobIDs: []string{"job-1"}}}}
+	store, err := storage.NewStorage(filepath.Join(t.TempDir(), "store.json"),
+		storage.WithIngestController(&controller),
+		storage.WithIngestRetries(1, 0),
+		storage.WithSecretKey([]byte("0123456789abcdef0123456789abcdef")),
+	)
+	if err != nil {
+		t.Fatalf("NewStorage: %v", err)
+	}
+	handler := NewHandler(store, auth.NewSessionManager(24*time.Hour))
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Simulcast", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+
+	send := func(method, path string, user models.User, body string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(method, "/api/channels/"+channel.ID+path, strings.NewReader(body)), user)
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		return rec
+	}
+
+	const streamKey = "live_123456_secret"
+	rec := send(http.MethodPost, "/restreams", owner, `{"label":"Elsewhere","url":"rtmp://live.example.com/app","streamKey":"`+streamKey+`"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if strings.Contains(rec.Body.String(), streamKey) || strings.Contains(rec.Body.String(), "streamKey") {
+		t.Fatalf("expected the stream key withheld, got %s", rec.Body.String())
+	}
+	var created restreamTargetResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode target: %v", err)
+	}
+	if !created.Enabled || created.URL != "rtmp://live.example.com/app" {
+		t.Fatalf("expected an enabled target, got %+v", created)
+	}
+	stored, err := store.ListRestreamTargets(channel.ID)
+	if err != nil || len(stored) != 1 || strings.Contains(stored[0].StreamKeyCiphertext, streamKey) {
+		t.Fatalf("expected the stored key sealed, got %+v (err %v)", stored, err)
+	}
+
+	if rec := send(http.MethodGet, "/restreams", owner, ""); rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), streamKey) {
+		t.Fatalf("expected a list without keys, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = send(http.MethodPost, "/restreams", owner, `{"label":"Web","url":"https://live.example.com/app","streamKey":"key"}`)
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a non-RTMP url, got %d", rec.Code)
+	}
+	rec = send(http.MethodPatch, "/restreams/"+created.ID, owner, `{"enabled":false}`)
+	if rec.Code != http.StatusOK || strings.Contains(rec.Body.String(), `"enabled":true`) {
+		t.Fatalf("expected the target disabled, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := send(http.MethodPatch, "/restreams/missing", owner, `{"enabled":true}`); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for an unknown target, got %d", rec.Code)
+	}
+
+	rec = send(http.MethodGet, "/stream/restreams", owner, "")
+	if rec.Code != http.StatusConflict || decodeAPIError(t, rec.Body.Bytes()).Error.Code != "channel_offline" {
+		t.Fatalf("expected 409 channel_offline, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, err := store.StartStream(channel.ID, []string{"720p"}); err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	controller.statuses = []ingest.RestreamStatus{{TargetID: created.ID, State: ingest.RestreamRetrying, Restarts: 2, LastError: "connection refused"}}
+	rec = send(http.MethodGet, "/stream/restreams", owner, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var statuses []restreamStatusResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &statuses); err != nil {
+		t.Fatalf("decode status: %v", err)
+	}
+	want := restreamStatusResponse{TargetID: created.ID, Label: "Elsewhere", State: ingest.RestreamRetrying, Restarts: 2, LastError: "connection refused"}
+	if len(statuses) != 1 || statuses[0] != want {
+		t.Fatalf("expected %+v, got %+v", want, statuses)
+	}
+
+	if rec := send(http.MethodDelete, "/restreams/"+created.ID, owner, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+}
+
+func TestRestreamTargetsRequireSecretKey(t *testing.T) {
+	handler, store := newTestHandler(t)
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Simulcast", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	req := withUser(httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/restreams", strings.NewReader(`{"label":"Elsewhere","url":"rtmp://live.example.com/app","streamKey":"key"}`)), owner)
+	rec := httptest.NewRecorder()
+	handler.ChannelByID(rec, req)
+	if rec.Code != http.StatusNotImplemented || decodeAPIError(t, rec.Body.Bytes()).Error.Code != "secret_key_unconfigured" {
+		t.Fatalf("expected 501 secret_key_unconfigured, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
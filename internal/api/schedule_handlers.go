@@ -0,0 +1,429 @@
+package api
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+const (
+	// scheduleFeedKeyLabel derives the key that signs followed-schedule feed
+	// tokens from CookieSigningKey.
+	scheduleFeedKeyLabel = "schedule-feed-token"
+	// scheduleFeedPath is the followed-schedule feed; it authenticates with
+	// the token query parameter so calendar apps can subscribe to it.
+	scheduleFeedPath = "/api/users/me/following/schedule.ics"
+	// scheduleCalendarContentType is sent with every calendar feed.
+	scheduleCalendarContentType = "text/calendar; charset=utf-8"
+)
+
+// channelScheduleFeed lets calendar apps and proxies reuse a channel's feed
+// for a while; schedules rarely change minute to minute.
+var channelScheduleFeed = conditionalGET{Endpoint: "channel_schedule_ics", CacheControl: "public, max-age=900"}
+
+// followedScheduleFeed belongs to one user, so only their client caches it.
+var followedScheduleFeed = conditionalGET{Endpoint: "followed_schedule_ics", CacheControl: "private, max-age=900"}
+
+type scheduleEntryRequest struct {
+	Title           string  `json:"title"`
+	Category        string  `json:"category"`
+	StartsAt        string  `json:"startsAt"`
+	DurationMinutes int     `json:"durationMinutes"`
+	TimeZone        string  `json:"timeZone"`
+	Recurrence      string  `json:"recurrence"`
+	RepeatUntil     *string `json:"repeatUntil"`
+}
+
+// scheduleEntryUpdateRequest changes the fields present in the body. An
+// empty repeatUntil removes the end of a recurrence.
+type scheduleEntryUpdateRequest struct {
+	Title           *string `json:"title"`
+	Category        *string `json:"category"`
+	StartsAt        *string `json:"startsAt"`
+	DurationMinutes *int    `json:"durationMinutes"`
+	TimeZone        *string `json:"timeZone"`
+	Recurrence      *string `json:"recurrence"`
+	RepeatUntil     *string `json:"repeatUntil"`
+}
+
+type scheduleEntryResponse struct {
+	ID              string  `json:"id"`
+	ChannelID       string  `json:"channelId"`
+	Title           string  `json:"title"`
+	Category        string  `json:"category,omitempty"`
+	StartsAt        string  `json:"startsAt"`
+	DurationMinutes int     `json:"durationMinutes"`
+	TimeZone        string  `json:"timeZone"`
+	Recurrence      string  `json:"recurrence,omitempty"`
+	RepeatUntil     *string `json:"repeatUntil,omitempty"`
+	Sequence        int     `json:"sequence"`
+	CreatedAt       string  `json:"createdAt"`
+	UpdatedAt       string  `json:"updatedAt"`
+}
+
+type scheduleFeedTokenResponse struct {
+	URL       string `json:"url"`
+	CreatedAt string `json:"createdAt"`
+}
+
+func newScheduleEntryResponse(entry models.ScheduleEntry) scheduleEntryResponse {
+	response := scheduleEntryResponse{
+		ID:              entry.ID,
+		ChannelID:       entry.ChannelID,
+		Title:           entry.Title,
+		Category:        entry.Category,
+		StartsAt:        entry.StartsAt.Format(time.RFC3339),
+		DurationMinutes: entry.DurationMinutes,
+		TimeZone:        entry.TimeZone,
+		Recurrence:      entry.Recurrence,
+		Sequence:        entry.Sequence,
+		CreatedAt:       entry.CreatedAt.Format(time.RFC3339Nano),
+		UpdatedAt:       entry.UpdatedAt.Format(time.RFC3339Nano),
+	}
+	if entry.RepeatUntil != nil {
+		until := entry.RepeatUntil.Format(time.RFC3339)
+		response.RepeatUntil = &until
+	}
+	return response
+}
+
+// parseScheduleTime parses an RFC3339 timestamp from a schedule request.
+// An empty value parses to the zero time.
+func parseScheduleTime(field, value string) (time.Time, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return time.Time{}, nil
+	}
+	parsed, err := time.Parse(time.RFC3339, value)
+	if err != nil {
+		return time.Time{}, ValidationError(field + " must be an RFC3339 timestamp")
+	}
+	return parsed, nil
+}
+
+func (req scheduleEntryRequest) params() (storage.ScheduleEntryParams, error) {
+	startsAt, err := parseScheduleTime("startsAt", req.StartsAt)
+	if err != nil {
+		return storage.ScheduleEntryParams{}, err
+	}
+	params := storage.ScheduleEntryParams{
+		Title:           req.Title,
+		Category:        req.Category,
+		StartsAt:        startsAt,
+		DurationMinutes: req.DurationMinutes,
+		TimeZone:        req.TimeZone,
+		Recurrence:      req.Recurrence,
+	}
+	if req.RepeatUntil != nil && strings.TrimSpace(*req.RepeatUntil) != "" {
+		until, err := parseScheduleTime("repeatUntil", *req.RepeatUntil)
+		if err != nil {
+			return storage.ScheduleEntryParams{}, err
+		}
+		params.RepeatUntil = &until
+	}
+	return params, nil
+}
+
+func (req scheduleEntryUpdateRequest) update() (storage.ScheduleEntryUpdate, error) {
+	update := storage.ScheduleEntryUpdate{
+		Title:           req.Title,
+		Category:        req.Category,
+		DurationMinutes: req.DurationMinutes,
+		TimeZone:        req.TimeZone,
+		Recurrence:      req.Recurrence,
+	}
+	if req.StartsAt != nil {
+		startsAt, err := parseScheduleTime("startsAt", *req.StartsAt)
+		if err != nil {
+			return storage.ScheduleEntryUpdate{}, err
+		}
+		update.StartsAt = &startsAt
+	}
+	if req.RepeatUntil != nil {
+		until, err := parseScheduleTime("repeatUntil", *req.RepeatUntil)
+		if err != nil {
+			return storage.ScheduleEntryUpdate{}, err
+		}
+		update.RepeatUntil = &until
+	}
+	return update, nil
+}
+
+// scheduleEntryError maps storage errors from the schedule methods.
+func scheduleEntryError(err error) RequestError {
+	var requestErr RequestError
+	switch {
+	case errors.As(err, &requestErr):
+		return requestErr
+	case errors.Is(err, storage.ErrScheduleEntryNotFound):
+		return RequestError{Status: http.StatusNotFound, Message: "schedule entry not found", Err: err}
+	case errors.Is(err, storage.ErrScheduleEntryLimit):
+		return RequestError{Status: http.StatusConflict, CodeVal: "schedule_entry_limit", Message: fmt.Sprintf("a channel can have at most %d schedule entries", storage.MaxScheduleEntriesPerChannel), Err: err}
+	default:
+		return RequestError{Status: http.StatusBadRequest, Err: err}
+	}
+}
+
+// scheduleVisible reports whether the channel's schedule is public. The
+// schedules of channels whose owner is deactivated are hidden, as if the
+// channel were gone.
+func (h *Handler) scheduleVisible(channel models.Channel) bool {
+	owner, ok := h.Store.GetUser(channel.OwnerID)
+	return ok && !owner.Deactivated()
+}
+
+// handleChannelSchedule serves /api/channels/{id}/schedule and
+// /api/channels/{id}/schedule/{entryId}. Anyone can list the schedule;
+// channel managers add entries, and change (PATCH) or remove (DELETE) one.
+func (h *Handler) handleChannelSchedule(channel models.Channel, remaining []string, w http.ResponseWriter, r *http.Request) {
+	if len(remaining) > 1 {
+		WriteError(w, http.StatusNotFound, fmt.Errorf("unknown schedule path"))
+		return
+	}
+
+	if len(remaining) == 1 && strings.TrimSpace(remaining[0]) != "" {
+		entryID := strings.TrimSpace(remaining[0])
+		switch r.Method {
+		case http.MethodPatch:
+			if _, ok := h.ensureChannelAccess(w, r, channel); !ok {
+				return
+			}
+			var req scheduleEntryUpdateRequest
+			if !DecodeAndValidate(w, r, &req) {
+				return
+			}
+			update, err := req.update()
+			if err != nil {
+				WriteRequestError(w, scheduleEntryError(err))
+				return
+			}
+			entry, err := h.Store.UpdateScheduleEntry(channel.ID, entryID, update)
+			if err != nil {
+				WriteRequestError(w, scheduleEntryError(err))
+				return
+			}
+			WriteJSON(w, http.StatusOK, newScheduleEntryResponse(entry))
+		case http.MethodDelete:
+			if _, ok := h.ensureChannelAccess(w, r, channel); !ok {
+				return
+			}
+			if err := h.Store.DeleteScheduleEntry(channel.ID, entryID); err != nil {
+				WriteRequestError(w, scheduleEntryError(err))
+				return
+			}
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			WriteMethodNotAllowed(w, r, http.MethodPatch, http.MethodDelete)
+		}
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		if !h.scheduleVisible(channel) {
+			WriteError(w, http.StatusNotFound, fmt.Errorf("channel %s not found", channel.ID))
+			return
+		}
+		entries, err := h.Store.ListScheduleEntries(channel.ID)
+		if err != nil {
+			writeStorageError(w, http.StatusInternalServerError, err)
+			return
+		}
+		response := make([]scheduleEntryResponse, 0, len(entries))
+		for _, entry := range entries {
+			response = append(response, newScheduleEntryResponse(entry))
+		}
+		WriteJSON(w, http.StatusOK, response)
+	case http.MethodPost:
+		if _, ok := h.ensureChannelAccess(w, r, channel); !ok {
+			return
+		}
+		var req scheduleEntryRequest
+		if !DecodeAndValidate(w, r, &req) {
+			return
+		}
+		params, err := req.params()
+		if err != nil {
+			WriteRequestError(w, scheduleEntryError(err))
+			return
+		}
+		entry, err := h.Store.CreateScheduleEntry(channel.ID, params)
+		if err != nil {
+			WriteRequestError(w, scheduleEntryError(err))
+			return
+		}
+		WriteJSON(w, http.StatusCreated, newScheduleEntryResponse(entry))
+	default:
+		WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodPost)
+	}
+}
+
+// channelScheduleCalendar serves GET /api/channels/{id}/schedule.ics, the
+// channel's schedule as a public iCalendar feed.
+func (h *Handler) channelScheduleCalendar(channelID string, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	channel, ok := h.Store.GetChannel(channelID)
+	if !ok || !h.scheduleVisible(channel) {
+		WriteError(w, http.StatusNotFound, fmt.Errorf("channel %s not found", channelID))
+		return
+	}
+	entries, err := h.Store.ListScheduleEntries(channel.ID)
+	if err != nil {
+		writeStorageError(w, http.StatusInternalServerError, err)
+		return
+	}
+	calendar := renderScheduleCalendar(channel.Title, []scheduleFeedSource{{Channel: channel, Entries: entries}}, false, h.now())
+	h.writeCalendar(w, r, channelScheduleFeed, calendar)
+}
+
+// followedScheduleCalendar serves GET /api/users/me/following/schedule.ics,
+// the schedules of every channel the user follows in one feed. Calendar
+// apps cannot sign in, so the feed also accepts the token from the URL
+// issued by /api/users/me/schedule-feed.
+func (h *Handler) followedScheduleCalendar(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	var (
+		user models.User
+		ok   bool
+	)
+	if token := strings.TrimSpace(r.URL.Query().Get("token")); token != "" {
+		user, ok = h.scheduleFeedUser(token)
+		if !ok {
+			WriteError(w, http.StatusUnauthorized, fmt.Errorf("invalid or revoked schedule feed token"))
+			return
+		}
+	} else if user, ok = h.requireAuthenticatedUser(w, r); !ok {
+		return
+	}
+	channelIDs, err := h.Store.ListFollowedChannelIDs(user.ID)
+	if err != nil {
+		writeStorageError(w, http.StatusInternalServerError, err)
+		return
+	}
+	sources := make([]scheduleFeedSource, 0, len(channelIDs))
+	for _, channelID := range channelIDs {
+		channel, exists := h.Store.GetChannel(channelID)
+		if !exists || !h.scheduleVisible(channel) {
+			continue
+		}
+		entries, err := h.Store.ListScheduleEntries(channel.ID)
+		if err != nil {
+			writeStorageError(w, http.StatusInternalServerError, err)
+			return
+		}
+		sources = append(sources, scheduleFeedSource{Channel: channel, Entries: entries})
+	}
+	calendar := renderScheduleCalendar("Followed channels on BitRiver Live", sources, true, h.now())
+	h.writeCalendar(w, r, followedScheduleFeed, calendar)
+}
+
+func (h *Handler) writeCalendar(w http.ResponseWriter, r *http.Request, conditional conditionalGET, calendar string) {
+	w.Header().Set("Content-Type", scheduleCalendarContentType)
+	body := []byte(calendar)
+	conditional.write(w, r, etagFor(body), body)
+}
+
+// userScheduleFeed serves /api/users/me/schedule-feed. GET returns the
+// current followed-schedule feed URL, POST issues a new one and DELETE
+// revokes it; issuing a new URL revokes the previous one.
+func (h *Handler) userScheduleFeed(w http.ResponseWriter, r *http.Request) {
+	user, ok := h.requireAuthenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	if len(h.CookieSigningKey) == 0 {
+		WriteRequestError(w, RequestError{Status: http.StatusNotImplemented, CodeVal: "secret_key_unconfigured", Message: "schedule feeds require a server secret key"})
+		return
+	}
+	switch r.Method {
+	case http.MethodGet:
+		token, exists := h.Store.GetScheduleFeedToken(user.ID)
+		if !exists {
+			WriteError(w, http.StatusNotFound, fmt.Errorf("no schedule feed issued"))
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		WriteJSON(w, http.StatusOK, h.newScheduleFeedTokenResponse(r, token))
+	case http.MethodPost:
+		token, err := h.Store.IssueScheduleFeedToken(user.ID)
+		if err != nil {
+			writeStorageError(w, http.StatusInternalServerError, err)
+			return
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		WriteJSON(w, http.StatusCreated, h.newScheduleFeedTokenResponse(r, token))
+	case http.MethodDelete:
+		if err := h.Store.RevokeScheduleFeedToken(user.ID); err != nil {
+			writeStorageError(w, http.StatusInternalServerError, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodPost, http.MethodDelete)
+	}
+}
+
+func (h *Handler) newScheduleFeedTokenResponse(r *http.Request, token models.ScheduleFeedToken) scheduleFeedTokenResponse {
+	query := url.Values{"token": {h.scheduleFeedToken(token)}}
+	return scheduleFeedTokenResponse{
+		URL:       absoluteURL(requestBaseURL(r), scheduleFeedPath, query),
+		CreatedAt: token.CreatedAt.Format(time.RFC3339Nano),
+	}
+}
+
+// scheduleFeedToken renders the URL token for a stored feed token: the user
+// ID and nonce, signed with a key derived from CookieSigningKey so the
+// token cannot be guessed from the stored nonce alone.
+func (h *Handler) scheduleFeedToken(token models.ScheduleFeedToken) string {
+	payload := token.UserID + "." + token.Nonce
+	return payload + "." + hex.EncodeToString(h.scheduleFeedMAC(payload))
+}
+
+func (h *Handler) scheduleFeedMAC(payload string) []byte {
+	derive := hmac.New(sha256.New, h.CookieSigningKey)
+	derive.Write([]byte(scheduleFeedKeyLabel))
+	mac := hmac.New(sha256.New, derive.Sum(nil))
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// scheduleFeedUser resolves a feed URL token to its user. The token must
+// carry a valid signature and the nonce the user currently holds, so
+// revoking or reissuing the feed invalidates old URLs.
+func (h *Handler) scheduleFeedUser(raw string) (models.User, bool) {
+	if len(h.CookieSigningKey) == 0 {
+		return models.User{}, false
+	}
+	parts := strings.Split(raw, ".")
+	if len(parts) != 3 {
+		return models.User{}, false
+	}
+	signature, err := hex.DecodeString(parts[2])
+	if err != nil || !hmac.Equal(signature, h.scheduleFeedMAC(parts[0]+"."+parts[1])) {
+		return models.User{}, false
+	}
+	token, ok := h.Store.GetScheduleFeedToken(parts[0])
+	if !ok || !hmac.Equal([]byte(token.Nonce), []byte(parts[1])) {
+		return models.User{}, false
+	}
+	user, ok := h.Store.GetUser(token.UserID)
+	if !ok || user.Deactivated() {
+		return models.User{}, false
+	}
+	return user, true
+}
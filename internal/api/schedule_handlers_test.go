@@ -0,0 +1,347 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"regexp"
+	"strings"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+type scheduleFixture struct {
+	handler *Handler
+	store   *storage.Storage
+	owner   models.User
+	channel models.Channel
+	now     *time.Time
+}
+
+func newScheduleFixture(t *testing.T) scheduleFixture {
+	t.Helper()
+	handler, store := newTestHandler(t)
+	handler.CookieSigningKey = []byte("schedule-test-secret")
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	handler.Now = func() time.Time { return now }
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Night Shift", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	return scheduleFixture{handler: handler, store: store, owner: owner, channel: channel, now: &now}
+}
+
+func (f scheduleFixture) channelRequest(method, path string, user *models.User, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, "/api/channels/"+f.channel.ID+path, strings.NewReader(body))
+	if user != nil {
+		req = withUser(req, *user)
+	}
+	rec := httptest.NewRecorder()
+	f.handler.ChannelByID(rec, req)
+	return rec
+}
+
+func (f scheduleFixture) createEntry(t *testing.T, body string) scheduleEntryResponse {
+	t.Helper()
+	rec := f.channelRequest(http.MethodPost, "/schedule", &f.owner, body)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var entry scheduleEntryResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &entry); err != nil {
+		t.Fatalf("decode entry: %v", err)
+	}
+	return entry
+}
+
+func (f scheduleFixture) calendar(t *testing.T) string {
+	t.Helper()
+	rec := f.channelRequest(http.MethodGet, "/schedule.ics", nil, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	return rec.Body.String()
+}
+
+// unfoldCalendar joins folded lines and splits the calendar into its
+// content lines.
+func unfoldCalendar(t *testing.T, calendar string) []string {
+	t.Helper()
+	for _, line := range strings.SplitAfter(calendar, "\r\n") {
+		if len(strings.TrimSuffix(line, "\r\n")) > icalLineLimit {
+			t.Fatalf("expected lines folded at %d octets, got %q", icalLineLimit, line)
+		}
+	}
+	return strings.Split(strings.TrimSuffix(strings.ReplaceAll(calendar, "\r\n ", ""), "\r\n"), "\r\n")
+}
+
+func calendarValues(lines []string, name string) []string {
+	var values []string
+	for _, line := range lines {
+		if value, ok := strings.CutPrefix(line, name+":"); ok {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+func TestChannelScheduleCalendarUsesRRULEForFixedOffsetZones(t *testing.T) {
+	f := newScheduleFixture(t)
+	entry := f.createEntry(t, `{"title":"Speedruns, part 1; late","startsAt":"2024-03-04T20:00:00+09:00","durationMinutes":120,"timeZone":"Asia/Tokyo","recurrence":"weekly","repeatUntil":"2024-06-01T00:00:00Z"}`)
+
+	rec := f.channelRequest(http.MethodGet, "/schedule.ics", nil, "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Content-Type"); got != scheduleCalendarContentType {
+		t.Fatalf("expected a calendar content type, got %q", got)
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "public, max-age=900" {
+		t.Fatalf("expected a public cache policy, got %q", got)
+	}
+	lines := unfoldCalendar(t, rec.Body.String())
+	if uids := calendarValues(lines, "UID"); len(uids) != 1 || uids[0] != entry.ID+"@bitriver-live" {
+		t.Fatalf("expected one recurring event, got UIDs %v", uids)
+	}
+	if got := calendarValues(lines, "RRULE"); len(got) != 1 || got[0] != "FREQ=WEEKLY;UNTIL=20240601T000000Z" {
+		t.Fatalf("expected a weekly RRULE, got %v", got)
+	}
+	if got := calendarValues(lines, "DTSTART"); len(got) != 1 || got[0] != "20240304T110000Z" {
+		t.Fatalf("expected the start in UTC, got %v", got)
+	}
+	if got := calendarValues(lines, "SUMMARY"); len(got) != 1 || got[0] != `Speedruns\, part 1\; late` {
+		t.Fatalf("expected an escaped summary, got %v", got)
+	}
+
+	conditional := httptest.NewRequest(http.MethodGet, "/api/channels/"+f.channel.ID+"/schedule.ics", nil)
+	conditional.Header.Set("If-None-Match", rec.Header().Get("ETag"))
+	notModified := httptest.NewRecorder()
+	f.handler.ChannelByID(notModified, conditional)
+	if notModified.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 for a matching ETag, got %d", notModified.Code)
+	}
+}
+
+func TestChannelScheduleCalendarExpandsAcrossDaylightSaving(t *testing.T) {
+	f := newScheduleFixture(t)
+	// 19:00 in New York on the Monday before clocks spring forward on
+	// 10 March 2024.
+	entry := f.createEntry(t, `{"title":"Weekly show","startsAt":"2024-03-04T19:00:00-05:00","durationMinutes":60,"timeZone":"America/New_York","recurrence":"weekly"}`)
+
+	lines := unfoldCalendar(t, f.calendar(t))
+	if rules := calendarValues(lines, "RRULE"); len(rules) != 0 {
+		t.Fatalf("expected discrete events for a zone with daylight saving, got RRULE %v", rules)
+	}
+	starts := calendarValues(lines, "DTSTART")
+	// 4 March through 29 April fall inside the 60-day window from 1 March.
+	if len(starts) != 9 {
+		t.Fatalf("expected 9 occurrences within 60 days, got %d: %v", len(starts), starts)
+	}
+	if starts[0] != "20240305T000000Z" || starts[1] != "20240311T230000Z" {
+		t.Fatalf("expected 19:00 local on both sides of the DST change, got %v", starts[:2])
+	}
+	uids := calendarValues(lines, "UID")
+	if uids[0] != entry.ID+"-20240304@bitriver-live" || uids[1] != entry.ID+"-20240311@bitriver-live" {
+		t.Fatalf("expected per-occurrence UIDs keyed by local date, got %v", uids[:2])
+	}
+
+	// A week later the first occurrence has ended and drops out, and the
+	// remaining ones keep their UIDs.
+	*f.now = f.now.AddDate(0, 0, 7)
+	later := calendarValues(unfoldCalendar(t, f.calendar(t)), "UID")
+	if later[0] != uids[1] {
+		t.Fatalf("expected occurrence UIDs stable over time, got %v then %v", uids, later)
+	}
+}
+
+func TestChannelScheduleCalendarKeepsUIDsAcrossEdits(t *testing.T) {
+	f := newScheduleFixture(t)
+	entry := f.createEntry(t, `{"title":"Launch stream","category":"Science","startsAt":"2024-03-02T18:00:00Z","durationMinutes":90}`)
+	before := unfoldCalendar(t, f.calendar(t))
+	if got := calendarValues(before, "SEQUENCE"); len(got) != 1 || got[0] != "0" {
+		t.Fatalf("expected sequence 0, got %v", got)
+	}
+
+	rec := f.channelRequest(http.MethodPatch, "/schedule/"+entry.ID, &f.owner, `{"title":"Launch stream (moved)","startsAt":"2024-03-03T18:00:00Z"}`)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	after := unfoldCalendar(t, f.calendar(t))
+	if got, want := calendarValues(after, "UID"), calendarValues(before, "UID"); len(got) != 1 || got[0] != want[0] {
+		t.Fatalf("expected the UID kept across edits, got %v then %v", want, got)
+	}
+	if got := calendarValues(after, "SEQUENCE"); len(got) != 1 || got[0] != "1" {
+		t.Fatalf("expected the edit to bump the sequence, got %v", got)
+	}
+	if got := calendarValues(after, "DTSTART"); got[0] != "20240303T180000Z" {
+		t.Fatalf("expected the moved start, got %v", got)
+	}
+
+	*f.now = time.Date(2024, 3, 4, 0, 0, 0, 0, time.UTC)
+	if got := calendarValues(unfoldCalendar(t, f.calendar(t)), "UID"); len(got) != 0 {
+		t.Fatalf("expected a finished one-off stream dropped, got %v", got)
+	}
+}
+
+func TestChannelScheduleManagement(t *testing.T) {
+	f := newScheduleFixture(t)
+	viewer, err := f.store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+
+	body := `{"title":"Show","startsAt":"2024-03-02T18:00:00Z","durationMinutes":60}`
+	if rec := f.channelRequest(http.MethodPost, "/schedule", &viewer, body); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for a non-manager, got %d", rec.Code)
+	}
+	if rec := f.channelRequest(http.MethodPost, "/schedule", &f.owner, `{"title":"Show","startsAt":"tomorrow","durationMinutes":60}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for a malformed start, got %d", rec.Code)
+	}
+	if rec := f.channelRequest(http.MethodPost, "/schedule", &f.owner, `{"title":"Show","startsAt":"2024-03-02T18:00:00Z","durationMinutes":60,"timeZone":"Nowhere/Special"}`); rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected 400 for an unknown time zone, got %d", rec.Code)
+	}
+	entry := f.createEntry(t, body)
+
+	rec := f.channelRequest(http.MethodGet, "/schedule", nil, "")
+	var listed []scheduleEntryResponse
+	if rec.Code != http.StatusOK || json.Unmarshal(rec.Body.Bytes(), &listed) != nil || len(listed) != 1 || listed[0].TimeZone != "UTC" {
+		t.Fatalf("expected the entry listed publicly in UTC, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := f.channelRequest(http.MethodDelete, "/schedule/"+entry.ID, &viewer, ""); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 deleting as a non-manager, got %d", rec.Code)
+	}
+	if rec := f.channelRequest(http.MethodDelete, "/schedule/"+entry.ID, &f.owner, ""); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+	if rec := f.channelRequest(http.MethodDelete, "/schedule/"+entry.ID, &f.owner, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 deleting twice, got %d", rec.Code)
+	}
+}
+
+func TestChannelScheduleHiddenForSuspendedAndDeletedChannels(t *testing.T) {
+	f := newScheduleFixture(t)
+	f.createEntry(t, `{"title":"Show","startsAt":"2024-03-02T18:00:00Z","durationMinutes":60}`)
+
+	deactivated := true
+	if _, err := f.store.UpdateUser(f.owner.ID, storage.UserUpdate{Deactivated: &deactivated}); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	for _, path := range []string{"/schedule.ics", "/schedule"} {
+		if rec := f.channelRequest(http.MethodGet, path, nil, ""); rec.Code != http.StatusNotFound {
+			t.Fatalf("expected 404 for %s of a suspended channel, got %d", path, rec.Code)
+		}
+	}
+
+	if err := f.store.DeleteChannel(f.channel.ID); err != nil {
+		t.Fatalf("DeleteChannel: %v", err)
+	}
+	if rec := f.channelRequest(http.MethodGet, "/schedule.ics", nil, ""); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 for a deleted channel, got %d", rec.Code)
+	}
+}
+
+func TestFollowedScheduleFeedTokens(t *testing.T) {
+	f := newScheduleFixture(t)
+	f.createEntry(t, `{"title":"Show","startsAt":"2024-03-02T18:00:00Z","durationMinutes":60}`)
+	viewer, err := f.store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if err := f.store.FollowChannel(viewer.ID, f.channel.ID); err != nil {
+		t.Fatalf("FollowChannel: %v", err)
+	}
+
+	feedToken := func(method string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(method, "/api/users/me/schedule-feed", nil), viewer)
+		rec := httptest.NewRecorder()
+		f.handler.UserByID(rec, req)
+		return rec
+	}
+	issue := func() string {
+		t.Helper()
+		rec := feedToken(http.MethodPost)
+		if rec.Code != http.StatusCreated {
+			t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var issued scheduleFeedTokenResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &issued); err != nil {
+			t.Fatalf("decode feed token: %v", err)
+		}
+		parsed, err := url.Parse(issued.URL)
+		if err != nil || parsed.Path != scheduleFeedPath {
+			t.Fatalf("expected a feed URL, got %q", issued.URL)
+		}
+		return parsed.Query().Get("token")
+	}
+	fetch := func(token string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, scheduleFeedPath+"?token="+url.QueryEscape(token), nil)
+		rec := httptest.NewRecorder()
+		f.handler.UserByID(rec, req)
+		return rec
+	}
+
+	if rec := feedToken(http.MethodGet); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected 404 before a feed is issued, got %d", rec.Code)
+	}
+	first := issue()
+	rec := fetch(first)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 with a valid token, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Cache-Control"); !strings.HasPrefix(got, "private") {
+		t.Fatalf("expected a private cache policy, got %q", got)
+	}
+	if got := calendarValues(unfoldCalendar(t, rec.Body.String()), "SUMMARY"); len(got) != 1 || got[0] != "Night Shift: Show" {
+		t.Fatalf("expected the followed channel's entry labelled, got %v", got)
+	}
+
+	forged := regexp.MustCompile(`\.[0-9a-f]+$`).ReplaceAllString(first, ".00")
+	if rec := fetch(forged); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 for a forged signature, got %d", rec.Code)
+	}
+
+	second := issue()
+	if rec := fetch(first); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected reissuing to revoke the old URL, got %d", rec.Code)
+	}
+	if rec := fetch(second); rec.Code != http.StatusOK {
+		t.Fatalf("expected the new URL to work, got %d", rec.Code)
+	}
+	if rec := feedToken(http.MethodDelete); rec.Code != http.StatusNoContent {
+		t.Fatalf("expected 204, got %d", rec.Code)
+	}
+	if rec := fetch(second); rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 after revoking, got %d", rec.Code)
+	}
+	unauthenticated := httptest.NewRecorder()
+	f.handler.UserByID(unauthenticated, httptest.NewRequest(http.MethodGet, scheduleFeedPath, nil))
+	if unauthenticated.Code != http.StatusUnauthorized {
+		t.Fatalf("expected 401 without a token or session, got %d", unauthenticated.Code)
+	}
+
+	f.handler.CookieSigningKey = nil
+	if rec := feedToken(http.MethodPost); rec.Code != http.StatusNotImplemented {
+		t.Fatalf("expected 501 without a secret key, got %d", rec.Code)
+	}
+}
+
+func TestScheduleCalendarFoldsLongLines(t *testing.T) {
+	var w icalWriter
+	w.line("SUMMARY", strings.Repeat("é", 60))
+	for _, line := range strings.Split(strings.TrimSuffix(w.String(), "\r\n"), "\r\n") {
+		if len(line) > icalLineLimit {
+			t.Fatalf("expected folded lines of at most %d octets, got %d", icalLineLimit, len(line))
+		}
+	}
+	if unfolded := strings.ReplaceAll(w.String(), "\r\n ", ""); unfolded != "SUMMARY:"+strings.Repeat("é", 60)+"\r\n" {
+		t.Fatalf("expected folding to keep characters intact, got %q", unfolded)
+	}
+}
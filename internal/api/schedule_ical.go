@@ -0,0 +1,186 @@
+package api
+
+import (
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+const (
+	// scheduleExpansionWindow is how far ahead weekly entries in zones with
+	// daylight saving are listed as separate events.
+	scheduleExpansionWindow = 60 * 24 * time.Hour
+	// scheduleUIDDomain scopes event UIDs to this service.
+	scheduleUIDDomain = "bitriver-live"
+	// icalLineLimit is the longest content line, in octets, before it is
+	// folded (RFC 5545 section 3.1).
+	icalLineLimit  = 75
+	icalTimeFormat = "20060102T150405Z"
+)
+
+// scheduleFeedSource is one channel's schedule in a calendar feed.
+type scheduleFeedSource struct {
+	Channel models.Channel
+	Entries []models.ScheduleEntry
+}
+
+// icalWriter builds an iCalendar object with CRLF line endings and folded
+// long lines.
+type icalWriter struct {
+	b strings.Builder
+}
+
+func (w *icalWriter) line(name, value string) {
+	content := name + ":" + value
+	limit := icalLineLimit
+	for len(content) > limit {
+		cut := limit
+		for !utf8.RuneStart(content[cut]) {
+			cut--
+		}
+		w.b.WriteString(content[:cut])
+		w.b.WriteString("\r\n ")
+		content = content[cut:]
+		// Continuation lines start with a space that counts toward the
+		// limit.
+		limit = icalLineLimit - 1
+	}
+	w.b.WriteString(content)
+	w.b.WriteString("\r\n")
+}
+
+func (w *icalWriter) String() string {
+	return w.b.String()
+}
+
+// icalText escapes a TEXT value (RFC 5545 section 3.3.11).
+func icalText(value string) string {
+	return strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\r\n", `\n`, "\n", `\n`, "\r", `\n`).Replace(value)
+}
+
+func icalTime(t time.Time) string {
+	return t.UTC().Format(icalTimeFormat)
+}
+
+// scheduleHasFixedOffset reports whether loc keeps one UTC offset all year
+// around now, so a weekly entry recurs at the same UTC time and can be sent
+// as a single event with an RRULE in UTC.
+func scheduleHasFixedOffset(loc *time.Location, now time.Time) bool {
+	year := now.In(loc).Year()
+	_, offset := time.Date(year, time.January, 1, 0, 0, 0, 0, loc).Zone()
+	for _, probe := range []time.Time{
+		time.Date(year, time.July, 1, 0, 0, 0, 0, loc),
+		time.Date(year+1, time.January, 1, 0, 0, 0, 0, loc),
+		time.Date(year+1, time.July, 1, 0, 0, 0, 0, loc),
+	} {
+		if _, other := probe.Zone(); other != offset {
+			return false
+		}
+	}
+	return true
+}
+
+// scheduleOccurrences returns the starts of a weekly entry that have not
+// ended by now and begin within scheduleExpansionWindow. Each occurrence
+// keeps the wall-clock time of the first one in the entry's zone, so its
+// UTC time moves when daylight saving starts or ends.
+func scheduleOccurrences(entry models.ScheduleEntry, loc *time.Location, now time.Time) []time.Time {
+	local := entry.StartsAt.In(loc)
+	duration := time.Duration(entry.DurationMinutes) * time.Minute
+	horizon := now.Add(scheduleExpansionWindow)
+	// Skip the weeks that ended long ago; a day of slack covers offset
+	// changes.
+	week := 0
+	if elapsed := now.Sub(entry.StartsAt) - duration - 24*time.Hour; elapsed > 0 {
+		week = int(elapsed / (7 * 24 * time.Hour))
+	}
+	var starts []time.Time
+	for ; ; week++ {
+		start := time.Date(local.Year(), local.Month(), local.Day()+7*week, local.Hour(), local.Minute(), local.Second(), 0, loc)
+		if !start.Before(horizon) || (entry.RepeatUntil != nil && start.After(*entry.RepeatUntil)) {
+			return starts
+		}
+		if start.Add(duration).After(now) {
+			starts = append(starts, start)
+		}
+	}
+}
+
+// renderScheduleCalendar renders the schedules as an iCalendar feed. Events
+// keep their UIDs across edits and carry the entry's sequence, so calendar
+// apps update them in place. labelChannels prefixes event titles with the
+// channel for feeds that mix channels.
+func renderScheduleCalendar(name string, sources []scheduleFeedSource, labelChannels bool, now time.Time) string {
+	var w icalWriter
+	w.line("BEGIN", "VCALENDAR")
+	w.line("VERSION", "2.0")
+	w.line("PRODID", "-//BitRiver Live//Schedule//EN")
+	w.line("CALSCALE", "GREGORIAN")
+	w.line("METHOD", "PUBLISH")
+	w.line("X-WR-CALNAME", icalText(name))
+	w.line("REFRESH-INTERVAL;VALUE=DURATION", "PT1H")
+	for _, source := range sources {
+		for _, entry := range source.Entries {
+			writeScheduleEntry(&w, source.Channel, entry, labelChannels, now)
+		}
+	}
+	w.line("END", "VCALENDAR")
+	return w.String()
+}
+
+func writeScheduleEntry(w *icalWriter, channel models.Channel, entry models.ScheduleEntry, labelChannels bool, now time.Time) {
+	loc, err := storage.LoadScheduleLocation(entry.TimeZone)
+	if err != nil {
+		loc = time.UTC
+	}
+	duration := time.Duration(entry.DurationMinutes) * time.Minute
+	uid := entry.ID + "@" + scheduleUIDDomain
+
+	if entry.Recurrence != models.ScheduleRecurrenceWeekly {
+		if entry.StartsAt.Add(duration).After(now) {
+			writeScheduleEvent(w, channel, entry, uid, entry.StartsAt, duration, "", labelChannels)
+		}
+		return
+	}
+	if entry.RepeatUntil != nil && entry.RepeatUntil.Add(duration).Before(now) {
+		return
+	}
+	if scheduleHasFixedOffset(loc, now) {
+		rule := "FREQ=WEEKLY"
+		if entry.RepeatUntil != nil {
+			rule += ";UNTIL=" + icalTime(*entry.RepeatUntil)
+		}
+		writeScheduleEvent(w, channel, entry, uid, entry.StartsAt, duration, rule, labelChannels)
+		return
+	}
+	for _, start := range scheduleOccurrences(entry, loc, now) {
+		occurrenceUID := entry.ID + "-" + start.In(loc).Format("20060102") + "@" + scheduleUIDDomain
+		writeScheduleEvent(w, channel, entry, occurrenceUID, start, duration, "", labelChannels)
+	}
+}
+
+func writeScheduleEvent(w *icalWriter, channel models.Channel, entry models.ScheduleEntry, uid string, start time.Time, duration time.Duration, rule string, labelChannels bool) {
+	summary := entry.Title
+	if labelChannels {
+		summary = channel.Title + ": " + entry.Title
+	}
+	w.line("BEGIN", "VEVENT")
+	w.line("UID", uid)
+	w.line("DTSTAMP", icalTime(entry.UpdatedAt))
+	w.line("SEQUENCE", strconv.Itoa(entry.Sequence))
+	w.line("DTSTART", icalTime(start))
+	w.line("DTEND", icalTime(start.Add(duration)))
+	if rule != "" {
+		w.line("RRULE", rule)
+	}
+	w.line("SUMMARY", icalText(summary))
+	w.line("DESCRIPTION", icalText("Live on "+channel.Title))
+	if entry.Category != "" {
+		w.line("CATEGORIES", icalText(entry.Category))
+	}
+	w.line("END", "VEVENT")
+}
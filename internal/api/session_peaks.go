@@ -0,0 +1,119 @@
+package api
+
+import (
+	"errors"
+	"sync"
+	"time"
+
+	"bitriver-live/internal/storage"
+)
+
+// defaultSessionPeakInterval is the SessionPeakInterval used when none is
+// configured.
+const defaultSessionPeakInterval = 30 * time.Second
+
+func (h *Handler) sessionPeakInterval() time.Duration {
+	if h.SessionPeakInterval > 0 {
+		return h.SessionPeakInterval
+	}
+	return defaultSessionPeakInterval
+}
+
+// sessionPeakWriter throttles writes of live session peaks. A raise that
+// arrives within the interval after a write is held as the session's
+// pending peak and written once the interval ends. The zero value is ready
+// to use.
+type sessionPeakWriter struct {
+	mu       sync.Mutex
+	sessions map[string]*sessionPeakState
+}
+
+type sessionPeakState struct {
+	written time.Time
+	pending int
+	flush   *time.Timer
+}
+
+// next returns the peak to write now, or false when the session was written
+// within interval. A peak that has to wait raises the session's pending
+// peak, and flush is scheduled for when the interval ends.
+func (w *sessionPeakWriter) next(sessionID string, peak int, interval time.Duration, now time.Time, flush func()) (int, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	if w.sessions == nil {
+		w.sessions = make(map[string]*sessionPeakState)
+	}
+	for id, state := range w.sessions {
+		if state.flush == nil && now.Sub(state.written) >= interval {
+			delete(w.sessions, id)
+		}
+	}
+	state, ok := w.sessions[sessionID]
+	if !ok {
+		w.sessions[sessionID] = &sessionPeakState{written: now}
+		return peak, true
+	}
+	if now.Sub(state.written) >= interval {
+		// The interval ended before the scheduled flush ran.
+		if state.flush != nil {
+			state.flush.Stop()
+			state.flush = nil
+		}
+		state.written = now
+		peak = max(peak, state.pending)
+		state.pending = 0
+		return peak, true
+	}
+	state.pending = max(state.pending, peak)
+	if state.flush == nil {
+		state.flush = time.AfterFunc(state.written.Add(interval).Sub(now), flush)
+	}
+	return 0, false
+}
+
+// take returns and clears the session's pending peak, or false when nothing
+// is pending.
+func (w *sessionPeakWriter) take(sessionID string, now time.Time) (int, bool) {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	state, ok := w.sessions[sessionID]
+	if !ok {
+		return 0, false
+	}
+	state.flush = nil
+	if state.pending == 0 {
+		return 0, false
+	}
+	peak := state.pending
+	state.pending = 0
+	state.written = now
+	return peak, true
+}
+
+// recordSessionPeak persists a new viewer peak on the channel's live
+// session, writing at most once per SessionPeakInterval for each session.
+// A raise inside the interval is written when the interval ends, so the
+// stored peak catches up even if no other viewer joins.
+func (h *Handler) recordSessionPeak(channelID string, peak int) {
+	if peak <= 0 {
+		return
+	}
+	session, ok := h.Store.CurrentStreamSession(channelID)
+	if !ok {
+		return
+	}
+	flush := func() {
+		if pending, ok := h.sessionPeaks.take(session.ID, h.now()); ok {
+			h.writeSessionPeak(channelID, session.ID, pending)
+		}
+	}
+	if peak, ok := h.sessionPeaks.next(session.ID, peak, h.sessionPeakInterval(), h.now(), flush); ok {
+		h.writeSessionPeak(channelID, session.ID, peak)
+	}
+}
+
+func (h *Handler) writeSessionPeak(channelID, sessionID string, peak int) {
+	if _, err := h.Store.UpdateSessionPeak(sessionID, peak); err != nil && !errors.Is(err, storage.ErrStreamSessionEnded) {
+		h.logger().Warn("failed to record session peak", "channel_id", channelID, "session_id", sessionID, "error", err)
+	}
+}
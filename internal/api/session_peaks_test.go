@@ -0,0 +1,226 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/storage"
+)
+
+func TestSRSPlayHooksPersistThrottledSessionPeak(t *testing.T) {
+	handler, store := newTestHandler(t)
+	handler.SRSHookToken = "secret"
+	handler.SessionPeakInterval = time.Minute
+	clock := time.Date(2026, 3, 1, 20, 0, 0, 0, time.UTC)
+	handler.Now = func() time.Time { return clock }
+
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Crowded", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	session, err := store.StartStream(channel.ID, []string{"720p"})
+	if err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+
+	hook := func(action string) {
+		t.Helper()
+		body, _ := json.Marshal(srsHookRequest{Action: action, Stream: channel.StreamKey})
+		rec := httptest.NewRecorder()
+		handler.SRSHook(rec, httptest.NewRequest(http.MethodPost, "/api/ingest/srs-hook?token=secret", bytes.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", action, rec.Code)
+		}
+	}
+	storedPeak := func() int {
+		t.Helper()
+		current, ok := store.CurrentStreamSession(channel.ID)
+		if !ok {
+			t.Fatal("expected a live session")
+		}
+		return current.PeakConcurrent
+	}
+
+	hook("on_play")
+	if peak := storedPeak(); peak != 1 {
+		t.Fatalf("expected the first viewer to be persisted, got peak %d", peak)
+	}
+	hook("on_play")
+	hook("on_play")
+	if peak := storedPeak(); peak != 1 {
+		t.Fatalf("expected writes within the interval to be throttled, got peak %d", peak)
+	}
+
+	clock = clock.Add(time.Minute)
+	hook("on_play")
+	if peak := storedPeak(); peak != 4 {
+		t.Fatalf("expected the peak to be persisted after the interval, got %d", peak)
+	}
+
+	hook("on_stop")
+	hook("on_stop")
+	clock = clock.Add(time.Minute)
+	hook("on_play")
+	if peak := storedPeak(); peak != 4 {
+		t.Fatalf("expected a rejoin below the peak not to lower it, got %d", peak)
+	}
+
+	stopped, err := store.StopStream(channel.ID, 0)
+	if err != nil {
+		t.Fatalf("StopStream: %v", err)
+	}
+	if stopped.ID != session.ID || stopped.PeakConcurrent != 4 {
+		t.Fatalf("expected stopping to keep the persisted peak 4, got %d", stopped.PeakConcurrent)
+	}
+}
+
+func TestSRSPlayHooksFlushThrottledSessionPeak(t *testing.T) {
+	handler, store := newTestHandler(t)
+	handler.SRSHookToken = "secret"
+	handler.SessionPeakInterval = 20 * time.Millisecond
+
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Brief", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	if _, err := store.StartStream(channel.ID, []string{"720p"}); err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+
+	for _, action := range []string{"on_play", "on_play", "on_play", "on_stop", "on_stop", "on_stop"} {
+		body, _ := json.Marshal(srsHookRequest{Action: action, Stream: channel.StreamKey})
+		rec := httptest.NewRecorder()
+		handler.SRSHook(rec, httptest.NewRequest(http.MethodPost, "/api/ingest/srs-hook?token=secret", bytes.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", action, rec.Code)
+		}
+	}
+
+	// Every viewer has left, so no further hook raises the peak; the held
+	// raise still has to reach storage while the stream is live.
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		current, ok := store.CurrentStreamSession(channel.ID)
+		if !ok {
+			t.Fatal("expected a live session")
+		}
+		if current.PeakConcurrent == 3 {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the throttled peak 3 to be persisted, got %d", current.PeakConcurrent)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestStopStreamTreatsPeakConcurrentAsFloor(t *testing.T) {
+	handler, store := newTestHandler(t)
+
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Floor", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+
+	stop := func(body string) sessionResponse {
+		t.Helper()
+		req := withUser(httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/stream/stop", bytes.NewBufferString(body)), owner)
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected stop status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var session sessionResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &session); err != nil {
+			t.Fatalf("decode session: %v", err)
+		}
+		return session
+	}
+
+	session, err := store.StartStream(channel.ID, []string{"720p"})
+	if err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	if _, err := store.UpdateSessionPeak(session.ID, 12); err != nil {
+		t.Fatalf("UpdateSessionPeak: %v", err)
+	}
+	if stopped := stop(`{"peakConcurrent":3}`); stopped.PeakConcurrent != 12 {
+		t.Fatalf("expected a lower reported peak to keep the tracked 12, got %d", stopped.PeakConcurrent)
+	}
+
+	if _, err := store.StartStream(channel.ID, []string{"720p"}); err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	if stopped := stop(`{"peakConcurrent":7}`); stopped.PeakConcurrent != 7 {
+		t.Fatalf("expected a higher reported peak to act as a floor, got %d", stopped.PeakConcurrent)
+	}
+}
+
+func TestStopStreamDropsTrackedPeakBeforeRestart(t *testing.T) {
+	handler, store := newTestHandler(t)
+	handler.SRSHookToken = "secret"
+
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Restart", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+
+	stop := func() sessionResponse {
+		t.Helper()
+		req := withUser(httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/stream/stop", bytes.NewBufferString(`{}`)), owner)
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected stop status 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var session sessionResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &session); err != nil {
+			t.Fatalf("decode session: %v", err)
+		}
+		return session
+	}
+
+	if _, err := store.StartStream(channel.ID, []string{"720p"}); err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	for i := 0; i < 3; i++ {
+		body, _ := json.Marshal(srsHookRequest{Action: "on_play", Stream: channel.StreamKey})
+		rec := httptest.NewRecorder()
+		handler.SRSHook(rec, httptest.NewRequest(http.MethodPost, "/api/ingest/srs-hook?token=secret", bytes.NewReader(body)))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("on_play: expected status 200, got %d", rec.Code)
+		}
+	}
+	if stopped := stop(); stopped.PeakConcurrent != 3 {
+		t.Fatalf("expected the first session to keep peak 3, got %d", stopped.PeakConcurrent)
+	}
+
+	// The encoder stays connected, so SRS sends no unpublish between the
+	// sessions.
+	if _, err := store.StartStream(channel.ID, []string{"720p"}); err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	if stopped := stop(); stopped.PeakConcurrent != 0 {
+		t.Fatalf("expected the second session not to inherit the earlier peak, got %d", stopped.PeakConcurrent)
+	}
+}
@@ -17,7 +17,14 @@ func constantTimeEqual(expected, provided string) bool {
 }
 
 func (h *Handler) srsHookAuthorized(r *http.Request) bool {
-	token := strings.TrimSpace(h.SRSHookToken)
+	return sharedTokenAuthorized(h.SRSHookToken, r)
+}
+
+// sharedTokenAuthorized reports whether r carries token as a bearer token or
+// in its token query parameter, for callers such as ingest servers that
+// cannot always set headers. An empty token authorizes nothing.
+func sharedTokenAuthorized(token string, r *http.Request) bool {
+	token = strings.TrimSpace(token)
 	if token == "" || r == nil {
 		return false
 	}
@@ -0,0 +1,134 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"bitriver-live/internal/authz"
+	"bitriver-live/internal/storage"
+)
+
+// defaultStatsRollupInterval is the StatsRollupInterval used when none is
+// configured. It matches the server's default job schedule.
+const defaultStatsRollupInterval = 15 * time.Minute
+
+// publicStatsFeed caches the public stat block for as long as a rollup
+// usually stays current.
+var publicStatsFeed = conditionalGET{Endpoint: "platform_stats", CacheControl: "public, max-age=300"}
+
+// publicStatsResponse is the "about" stat block anyone may read. Counts are
+// rounded down by roundPublicCount so they do not expose exact numbers.
+type publicStatsResponse struct {
+	Channels               int    `json:"channels"`
+	LiveChannels           int    `json:"liveChannels"`
+	RegisteredUsers        int    `json:"registeredUsers"`
+	HoursStreamedThisMonth int    `json:"hoursStreamedThisMonth"`
+	Day                    string `json:"day"`
+}
+
+type adminStatsResponse struct {
+	Day                      string `json:"day"`
+	TotalChannels            int    `json:"totalChannels"`
+	LiveChannels             int    `json:"liveChannels"`
+	RegisteredUsers          int    `json:"registeredUsers"`
+	NewUsersToday            int    `json:"newUsersToday"`
+	SessionsStartedToday     int    `json:"sessionsStartedToday"`
+	StreamedSecondsThisMonth int64  `json:"streamedSecondsThisMonth"`
+	ChatMessagesToday        int    `json:"chatMessagesToday"`
+	ComputedAt               string `json:"computedAt"`
+	// AgeSeconds is how long ago the rollup ran; Stale is set once that is
+	// more than twice the rollup interval, which means the job is not
+	// keeping up.
+	AgeSeconds int64 `json:"ageSeconds"`
+	Stale      bool  `json:"stale"`
+}
+
+// roundPublicCount rounds n down to the nearest ten below 1,000, the nearest
+// hundred below 100,000, and the nearest thousand above that.
+func roundPublicCount(n int64) int {
+	step := int64(1000)
+	switch {
+	case n < 0:
+		return 0
+	case n < 1000:
+		step = 10
+	case n < 100000:
+		step = 100
+	}
+	return int(n / step * step)
+}
+
+func newPublicStatsResponse(stats storage.PlatformStats) publicStatsResponse {
+	return publicStatsResponse{
+		Channels:               roundPublicCount(int64(stats.TotalChannels)),
+		LiveChannels:           stats.LiveChannels,
+		RegisteredUsers:        roundPublicCount(int64(stats.RegisteredUsers)),
+		HoursStreamedThisMonth: roundPublicCount(stats.StreamedSecondsThisMonth / 3600),
+		Day:                    stats.Day,
+	}
+}
+
+func (h *Handler) statsRollupInterval() time.Duration {
+	if h.StatsRollupInterval > 0 {
+		return h.StatsRollupInterval
+	}
+	return defaultStatsRollupInterval
+}
+
+func (h *Handler) newAdminStatsResponse(stats storage.PlatformStats, now time.Time) adminStatsResponse {
+	age := now.Sub(stats.ComputedAt)
+	if age < 0 {
+		age = 0
+	}
+	return adminStatsResponse{
+		Day:                      stats.Day,
+		TotalChannels:            stats.TotalChannels,
+		LiveChannels:             stats.LiveChannels,
+		RegisteredUsers:          stats.RegisteredUsers,
+		NewUsersToday:            stats.NewUsersToday,
+		SessionsStartedToday:     stats.SessionsStartedToday,
+		StreamedSecondsThisMonth: stats.StreamedSecondsThisMonth,
+		ChatMessagesToday:        stats.ChatMessagesToday,
+		ComputedAt:               stats.ComputedAt.UTC().Format(time.RFC3339),
+		AgeSeconds:               int64(age / time.Second),
+		Stale:                    age > 2*h.statsRollupInterval(),
+	}
+}
+
+// statsPending answers requests made before the first rollup has run.
+func statsPending(w http.ResponseWriter) {
+	w.Header().Set("Retry-After", "60")
+	WriteRequestError(w, RequestError{Status: http.StatusServiceUnavailable, CodeVal: "stats_pending", Message: "platform statistics have not been computed yet"})
+}
+
+// PlatformStats serves the public, rounded platform statistics.
+func (h *Handler) PlatformStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	stats, ok := h.Store.LatestPlatformStats()
+	if !ok {
+		statsPending(w)
+		return
+	}
+	h.writeETagJSON(w, r, publicStatsFeed, newPublicStatsResponse(stats))
+}
+
+// AdminPlatformStats serves the exact platform statistics along with how old
+// they are.
+func (h *Handler) AdminPlatformStats(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	if _, ok := h.requirePermission(w, r, authz.AnalyticsView); !ok {
+		return
+	}
+	stats, ok := h.Store.LatestPlatformStats()
+	if !ok {
+		statsPending(w)
+		return
+	}
+	WriteJSON(w, http.StatusOK, h.newAdminStatsResponse(stats, h.now()))
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/storage"
+)
+
+func TestRoundPublicCount(t *testing.T) {
+	cases := map[int64]int{
+		-5:      0,
+		7:       0,
+		19:      10,
+		999:     990,
+		1049:    1000,
+		54321:   54300,
+		123456:  123000,
+		9876543: 9876000,
+	}
+	for n, want := range cases {
+		if got := roundPublicCount(n); got != want {
+			t.Errorf("roundPublicCount(%d) = %d, want %d", n, got, want)
+		}
+	}
+}
+
+func TestPlatformStatsEndpoints(t *testing.T) {
+	handler, store := newTestHandler(t)
+	admin, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Admin", Email: "admin@example.com", Roles: []string{"admin"}})
+	if err != nil {
+		t.Fatalf("CreateUser admin: %v", err)
+	}
+	for i, title := range []string{"One", "Two", "Three"} {
+		if _, err := store.CreateChannel(admin.ID, title, "gaming", nil); err != nil {
+			t.Fatalf("CreateChannel %d: %v", i, err)
+		}
+	}
+	public := func(header http.Header) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/api/stats", nil)
+		for key, values := range header {
+			req.Header[key] = values
+		}
+		rec := httptest.NewRecorder()
+		handler.PlatformStats(rec, req)
+		return rec
+	}
+	private := func() *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(http.MethodGet, "/api/admin/stats", nil), admin)
+		rec := httptest.NewRecorder()
+		handler.AdminPlatformStats(rec, req)
+		return rec
+	}
+
+	rec := public(nil)
+	if rec.Code != http.StatusServiceUnavailable || decodeAPIError(t, rec.Body.Bytes()).Error.Code != "stats_pending" {
+		t.Fatalf("expected 503 stats_pending before the first rollup, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	computedAt := time.Now().UTC().Add(time.Second).Truncate(time.Second)
+	if _, err := store.RollupPlatformStats(context.Background(), computedAt); err != nil {
+		t.Fatalf("RollupPlatformStats: %v", err)
+	}
+
+	rec = public(nil)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if got := rec.Header().Get("Cache-Control"); got != "public, max-age=300" {
+		t.Fatalf("expected the public stats to be cacheable, got %q", got)
+	}
+	var publicStats publicStatsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &publicStats); err != nil {
+		t.Fatalf("decode public stats: %v", err)
+	}
+	if publicStats.Channels != 0 || publicStats.RegisteredUsers != 0 {
+		t.Fatalf("expected small counts to round down to zero, got %+v", publicStats)
+	}
+	if rec := public(http.Header{"If-None-Match": {rec.Header().Get("ETag")}}); rec.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 for a matching ETag, got %d", rec.Code)
+	}
+
+	handler.StatsRollupInterval = time.Minute
+	handler.Now = func() time.Time { return computedAt.Add(30 * time.Second) }
+	rec = private()
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var fresh adminStatsResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &fresh); err != nil {
+		t.Fatalf("decode admin stats: %v", err)
+	}
+	if fresh.TotalChannels != 3 || fresh.RegisteredUsers != 1 || fresh.AgeSeconds != 30 || fresh.Stale {
+		t.Fatalf("expected exact, fresh stats, got %+v", fresh)
+	}
+	if fresh.ComputedAt != computedAt.Format(time.RFC3339) {
+		t.Fatalf("expected computedAt %s, got %s", computedAt.Format(time.RFC3339), fresh.ComputedAt)
+	}
+
+	handler.Now = func() time.Time { return computedAt.Add(3 * time.Minute) }
+	var stale adminStatsResponse
+	if err := json.Unmarshal(private().Body.Bytes(), &stale); err != nil {
+		t.Fatalf("decode admin stats: %v", err)
+	}
+	if !stale.Stale || stale.AgeSeconds != 180 {
+		t.Fatalf("expected stats older than twice the interval to be stale, got %+v", stale)
+	}
+}
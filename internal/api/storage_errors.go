@@ -0,0 +1,45 @@
+package api
+
+import (
+	"errors"
+	"net/http"
+
+	"bitriver-live/internal/storage"
+)
+
+// storageRequestError maps the storage package's error classes to a
+// response: missing records are 404, values another record holds and
+// operations the record's state does not allow are 409, and rejected input
+// is 400. It reports false for errors the storage package leaves
+// unclassified.
+func storageRequestError(err error) (RequestError, bool) {
+	var (
+		notFound     *storage.NotFoundError
+		conflict     *storage.ConflictError
+		invalid      *storage.ValidationError
+		precondition *storage.PreconditionError
+	)
+	switch {
+	case errors.As(err, &notFound):
+		return RequestError{Status: http.StatusNotFound, CodeVal: "not_found", Message: notFound.Error(), Err: err}, true
+	case errors.As(err, &conflict):
+		return RequestError{Status: http.StatusConflict, CodeVal: "conflict", Message: conflict.Error(), Err: err}, true
+	case errors.As(err, &invalid):
+		return RequestError{Status: http.StatusBadRequest, CodeVal: "validation_failed", Message: invalid.Error(), Err: err}, true
+	case errors.As(err, &precondition):
+		return RequestError{Status: http.StatusConflict, CodeVal: "precondition_failed", Message: precondition.Error(), Err: err}, true
+	default:
+		return RequestError{}, false
+	}
+}
+
+// writeStorageError writes an error returned by the repository. Typed
+// storage errors get the status and code storageRequestError maps them to;
+// anything else is written with status.
+func writeStorageError(w http.ResponseWriter, status int, err error) {
+	if reqErr, ok := storageRequestError(err); ok {
+		WriteRequestError(w, reqErr)
+		return
+	}
+	WriteError(w, status, err)
+}
@@ -0,0 +1,111 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+func TestStorageRequestErrorFollowsWrapping(t *testing.T) {
+	missing := &storage.NotFoundError{Kind: "channel", ID: "abc"}
+	cases := []struct {
+		name   string
+		err    error
+		class  error
+		status int
+		code   string
+	}{
+		{"not found", fmt.Errorf("load channel: %w", missing), storage.ErrNotFound, http.StatusNotFound, "not_found"},
+		{"conflict", fmt.Errorf("create user: %w", &storage.ConflictError{Field: "email", Message: "email a@example.com already in use"}), storage.ErrConflict, http.StatusConflict, "conflict"},
+		{"validation", fmt.Errorf("outer: %w", fmt.Errorf("inner: %w", &storage.ValidationError{Field: "title", Message: "title is required"})), storage.ErrValidation, http.StatusBadRequest, "validation_failed"},
+		{"precondition", fmt.Errorf("start: %w", storage.ErrChannelAlreadyLive), storage.ErrPreconditionFailed, http.StatusConflict, "precondition_failed"},
+	}
+	for _, tc := range cases {
+		if !errors.Is(tc.err, tc.class) {
+			t.Fatalf("%s: expected errors.Is to find the class through the wrapping", tc.name)
+		}
+		reqErr, ok := storageRequestError(tc.err)
+		if !ok || reqErr.StatusCode() != tc.status || reqErr.Code() != tc.code {
+			t.Fatalf("%s: expected %d %s, got %+v (ok %v)", tc.name, tc.status, tc.code, reqErr, ok)
+		}
+		if strings.Contains(reqErr.ClientMessage(), ":") {
+			t.Fatalf("%s: expected the wrapping context to stay out of the message, got %q", tc.name, reqErr.ClientMessage())
+		}
+		if !errors.Is(reqErr, tc.class) {
+			t.Fatalf("%s: expected the response error to keep the storage error", tc.name)
+		}
+	}
+	var found *storage.NotFoundError
+	if reqErr, _ := storageRequestError(fmt.Errorf("wrapped: %w", missing)); !errors.As(reqErr, &found) || found.ID != "abc" {
+		t.Fatalf("expected errors.As to reach the not found error, got %#v", found)
+	}
+
+	if _, ok := storageRequestError(errors.New("disk full")); ok {
+		t.Fatal("expected an unclassified error to be left to the caller")
+	}
+	rec := httptest.NewRecorder()
+	writeStorageError(rec, http.StatusBadRequest, errors.New("disk full"))
+	if rec.Code != http.StatusBadRequest {
+		t.Fatalf("expected the fallback status for an unclassified error, got %d", rec.Code)
+	}
+}
+
+func storageErrorCode(t *testing.T, rec *httptest.ResponseRecorder) string {
+	t.Helper()
+	var body apiErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &body); err != nil {
+		t.Fatalf("decode error response: %v (%s)", err, rec.Body.String())
+	}
+	return body.Error.Code
+}
+
+func TestHandlersMapTypedStorageErrors(t *testing.T) {
+	h, store := newTestHandler(t)
+	owner, channel := newStatusChannel(t, store)
+	admin, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Admin", Email: "admin@example.com", Roles: []string{"admin"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	recording := newPublishedRecordings(t, store, channel.ID, 1)[0]
+
+	serve := func(handler http.HandlerFunc, user models.User, method, path, body string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(method, path, strings.NewReader(body)), user)
+		rec := httptest.NewRecorder()
+		handler(rec, req)
+		return rec
+	}
+
+	rec := serve(h.Users, admin, http.MethodPost, "/api/users", `{"displayName":"Copy","email":"creator@example.com"}`)
+	if rec.Code != http.StatusConflict || storageErrorCode(t, rec) != "conflict" {
+		t.Fatalf("duplicate email: expected 409 conflict, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = serve(h.RecordingByID, owner, http.MethodPatch, "/api/recordings/"+recording.ID, `{"title":"   "}`)
+	if rec.Code != http.StatusBadRequest || storageErrorCode(t, rec) != "validation_failed" {
+		t.Fatalf("empty title: expected 400 validation_failed, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = serve(h.RecordingByID, owner, http.MethodPut, "/api/recordings/"+recording.ID+"/chapters", `{"chapters":[{"offsetSeconds":-1,"title":"Before"}]}`)
+	if rec.Code != http.StatusBadRequest || storageErrorCode(t, rec) != "validation_failed" {
+		t.Fatalf("negative chapter: expected 400 validation_failed, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	if _, err := store.StartStream(channel.ID, []string{"720p"}); err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	rec = serve(h.ChannelByID, owner, http.MethodPost, "/api/channels/"+channel.ID+"/stream/start", `{"renditions":["720p"]}`)
+	if rec.Code != http.StatusConflict || storageErrorCode(t, rec) != "precondition_failed" {
+		t.Fatalf("start while live: expected 409 precondition_failed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = serve(h.ChannelByID, owner, http.MethodDelete, "/api/channels/"+channel.ID, "")
+	if rec.Code != http.StatusConflict || storageErrorCode(t, rec) != "precondition_failed" {
+		t.Fatalf("delete while live: expected 409 precondition_failed, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
@@ -0,0 +1,301 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+const (
+	// defaultKeyLeakSourceLimit is the KeyLeakSourceLimit used when none is
+	// configured.
+	defaultKeyLeakSourceLimit = 3
+	// defaultKeyLeakWindow is the KeyLeakWindow used when none is
+	// configured.
+	defaultKeyLeakWindow = 24 * time.Hour
+	// securityEventsDefaultWindow is how far back the security event list
+	// reaches when the request names no since.
+	securityEventsDefaultWindow = 30 * 24 * time.Hour
+)
+
+type securityEventResponse struct {
+	ID        string `json:"id"`
+	Kind      string `json:"kind"`
+	SourceIP  string `json:"sourceIp,omitempty"`
+	SessionID string `json:"sessionId,omitempty"`
+	Detail    string `json:"detail,omitempty"`
+	CreatedAt string `json:"createdAt"`
+}
+
+func newSecurityEventResponse(event models.SecurityEvent) securityEventResponse {
+	return securityEventResponse{
+		ID:        event.ID,
+		Kind:      event.Kind,
+		SourceIP:  event.SourceIP,
+		SessionID: event.SessionID,
+		Detail:    event.Detail,
+		CreatedAt: event.CreatedAt.Format(time.RFC3339Nano),
+	}
+}
+
+type rotateAndStopResponse struct {
+	Channel channelResponse  `json:"channel"`
+	Session *sessionResponse `json:"session,omitempty"`
+	// StopError is set when the key was rotated but stopping the stream
+	// failed; the stream can be stopped again separately.
+	StopError string `json:"stopError,omitempty"`
+}
+
+func (h *Handler) keyLeakSourceLimit() int {
+	if h.KeyLeakSourceLimit > 0 {
+		return h.KeyLeakSourceLimit
+	}
+	return defaultKeyLeakSourceLimit
+}
+
+func (h *Handler) keyLeakWindow() time.Duration {
+	if h.KeyLeakWindow > 0 {
+		return h.KeyLeakWindow
+	}
+	return defaultKeyLeakWindow
+}
+
+// publishSourceIP normalises the encoder address an ingest callback
+// reported, dropping any port. Unparseable addresses are treated as unknown.
+func publishSourceIP(raw string) string {
+	raw = strings.TrimSpace(raw)
+	if host, _, err := net.SplitHostPort(raw); err == nil {
+		raw = host
+	}
+	ip := net.ParseIP(strings.Trim(raw, "[]"))
+	if ip == nil {
+		return ""
+	}
+	return ip.String()
+}
+
+// publishNetwork groups an address into the network it was published from:
+// its /24 for IPv4 and its /48 for IPv6.
+func publishNetwork(source string) string {
+	ip := net.ParseIP(source)
+	if ip == nil {
+		return ""
+	}
+	if v4 := ip.To4(); v4 != nil {
+		return (&net.IPNet{IP: v4.Mask(net.CIDRMask(24, 32)), Mask: net.CIDRMask(24, 32)}).String()
+	}
+	return (&net.IPNet{IP: ip.Mask(net.CIDRMask(48, 128)), Mask: net.CIDRMask(48, 128)}).String()
+}
+
+// claimPublishSource ties the channel's live session to the address its
+// encoder publishes from. A publish from any other address while the session
+// is live is refused with 403, recorded as a security event, and reported to
+// the owner. It returns false once it has answered the request. Callbacks
+// that report no address are let through unchecked.
+func (h *Handler) claimPublishSource(channel models.Channel, source string, w http.ResponseWriter) bool {
+	if source == "" {
+		return true
+	}
+	now := h.now()
+	session, err := h.Store.ClaimPublishSource(channel.ID, source)
+	switch {
+	case errors.Is(err, storage.ErrPublishSourceConflict):
+		h.rejectPublish(channel, session, source, now)
+		WriteRequestError(w, RequestError{Status: http.StatusForbidden, CodeVal: "publish_source_conflict", Message: "the stream is already being published from another address"})
+		return false
+	case err != nil:
+		h.logger().Warn("failed to claim publish source", "channel_id", channel.ID, "source_ip", source, "error", err)
+		return true
+	}
+	h.checkKeySourceSpread(channel, now)
+	return true
+}
+
+// rejectPublish records a publish refused because the session is already
+// publishing from elsewhere, which means someone else holds the key.
+func (h *Handler) rejectPublish(channel models.Channel, session models.StreamSession, source string, at time.Time) {
+	if _, err := h.Store.CreateSecurityEvent(storage.SecurityEventParams{
+		ChannelID: channel.ID,
+		Kind:      models.SecurityEventPublishRejected,
+		SourceIP:  source,
+		SessionID: session.ID,
+		Detail:    "session already publishing from " + session.PublishSource,
+		At:        at,
+	}); err != nil {
+		h.logger().Warn("failed to record rejected publish", "channel_id", channel.ID, "error", err)
+	}
+	h.auditLogger().Info("audit", "action", "stream_key.publish_rejected", "channel_id", channel.ID, "session_id", session.ID, "source_ip", source, "active_source_ip", session.PublishSource, "at", at.Format(time.RFC3339Nano))
+	h.notifyChannelOwner(channel, models.SecurityEventPublishRejected,
+		fmt.Sprintf("Someone tried to publish to %s from %s at %s while you were live. If this was not you, rotate your stream key and end the stream.", channel.Title, source, at.Format(time.RFC3339)),
+		map[string]string{"sourceIp": source, "sessionId": session.ID, "at": at.Format(time.RFC3339Nano)}, at)
+	h.checkKeySourceSpread(channel, at)
+}
+
+// checkKeySourceSpread flags a channel whose key was published from more
+// than KeyLeakSourceLimit distinct networks within KeyLeakWindow. Sessions
+// and refused publishes from before the key was last rotated do not count,
+// and a channel is flagged at most once per window.
+func (h *Handler) checkKeySourceSpread(channel models.Channel, at time.Time) {
+	since := at.Add(-h.keyLeakWindow())
+	events, err := h.Store.ListSecurityEvents(channel.ID, since)
+	if err != nil {
+		h.logger().Warn("failed to list security events", "channel_id", channel.ID, "error", err)
+		return
+	}
+	for _, event := range events {
+		switch event.Kind {
+		case models.SecurityEventKeySourcesSpread:
+			return
+		case models.SecurityEventKeyRevoked:
+			if event.CreatedAt.After(since) {
+				since = event.CreatedAt
+			}
+		}
+	}
+	networks := make(map[string]struct{})
+	for _, event := range events {
+		if event.Kind == models.SecurityEventPublishRejected && !event.CreatedAt.Before(since) {
+			if network := publishNetwork(event.SourceIP); network != "" {
+				networks[network] = struct{}{}
+			}
+		}
+	}
+	sessions, err := h.Store.ListStreamSessions(channel.ID)
+	if err != nil {
+		h.logger().Warn("failed to list stream sessions", "channel_id", channel.ID, "error", err)
+		return
+	}
+	for _, session := range sessions {
+		if session.StartedAt.Before(since) {
+			continue
+		}
+		if network := publishNetwork(session.PublishSource); network != "" {
+			networks[network] = struct{}{}
+		}
+	}
+	limit := h.keyLeakSourceLimit()
+	if len(networks) <= limit {
+		return
+	}
+	detail := fmt.Sprintf("stream key used from %d networks within %s", len(networks), h.keyLeakWindow())
+	if _, err := h.Store.CreateSecurityEvent(storage.SecurityEventParams{
+		ChannelID: channel.ID,
+		Kind:      models.SecurityEventKeySourcesSpread,
+		Detail:    detail,
+		At:        at,
+	}); err != nil {
+		h.logger().Warn("failed to record key source spread", "channel_id", channel.ID, "error", err)
+		return
+	}
+	h.auditLogger().Info("audit", "action", "stream_key.sources_spread", "channel_id", channel.ID, "networks", len(networks), "limit", limit, "at", at.Format(time.RFC3339Nano))
+	h.notifyChannelOwner(channel, models.SecurityEventKeySourcesSpread,
+		fmt.Sprintf("The stream key for %s was used from %d different networks within %s. If you did not stream from all of them, rotate your stream key.", channel.Title, len(networks), h.keyLeakWindow()),
+		map[string]string{"networks": fmt.Sprint(len(networks)), "at": at.Format(time.RFC3339Nano)}, at)
+}
+
+// notifyChannelOwner sends the channel owner an in-app account notice unless
+// they turned those off.
+func (h *Handler) notifyChannelOwner(channel models.Channel, kind, message string, metadata map[string]string, at time.Time) {
+	prefs, err := h.Store.GetNotificationPreferences(channel.OwnerID)
+	if err != nil {
+		h.logger().Warn("load notification preferences for security notice", "user_id", channel.OwnerID, "error", err)
+		prefs = models.DefaultNotificationPreferences(channel.OwnerID)
+	}
+	if !prefs.Allows(models.NotificationCategoryAccount, models.NotificationDeliveryInApp) {
+		return
+	}
+	if _, err := h.Store.CreateNotification(storage.NotificationParams{
+		UserID:    channel.OwnerID,
+		Category:  models.NotificationCategoryAccount,
+		Kind:      kind,
+		ChannelID: channel.ID,
+		Message:   message,
+		Metadata:  metadata,
+		At:        at,
+	}); err != nil {
+		h.logger().Warn("failed to create security notification", "user_id", channel.OwnerID, "channel_id", channel.ID, "error", err)
+	}
+}
+
+// rotateKeyAndStopStream serves POST /api/channels/{id}/stream/rotate-and-stop,
+// the one-click answer to a leaked key. The key is replaced before the
+// stream is stopped so the leaked key stops working even when ingest fails
+// to shut down; the response then carries the failure in stopError.
+func (h *Handler) rotateKeyAndStopStream(actor models.User, channel models.Channel, w http.ResponseWriter, r *http.Request) {
+	updated, err := h.Store.RotateChannelStreamKey(channel.ID)
+	if err != nil {
+		writeStorageError(w, http.StatusBadRequest, err)
+		return
+	}
+	now := h.now()
+	resp := rotateAndStopResponse{Channel: newChannelResponse(updated)}
+	fields := []any{"action", "stream_key.rotate_and_stop", "user_id", actor.ID, "channel_id", channel.ID}
+
+	tracker := h.srsTracker()
+	ctx, cancel := context.WithTimeout(r.Context(), h.streamRequestBudget())
+	defer cancel()
+	session, err := h.Store.StopStreamContext(ctx, channel.ID, tracker.peak(channel.ID))
+	switch {
+	case errors.Is(err, storage.ErrChannelNotLive):
+	case err != nil:
+		resp.StopError = err.Error()
+		fields = append(fields, "stop_error", resp.StopError)
+	default:
+		tracker.clear(channel.ID)
+		stopped := newSessionResponse(session)
+		resp.Session = &stopped
+		fields = append(fields, "session_id", session.ID)
+	}
+	h.invalidateChannelCache(r.Context(), channel.ID)
+	h.recordKeyRevoked(channel, resp.Session != nil, now)
+	h.auditLogger().Info("audit", fields...)
+	WriteJSON(w, http.StatusOK, resp)
+}
+
+// recordKeyRevoked marks a key rotation in the channel's security events, so
+// the distinct-network check only counts publishes made with the new key.
+func (h *Handler) recordKeyRevoked(channel models.Channel, stopped bool, at time.Time) {
+	detail := "stream key rotated"
+	if stopped {
+		detail = "stream key rotated and stream ended"
+	}
+	if _, err := h.Store.CreateSecurityEvent(storage.SecurityEventParams{
+		ChannelID: channel.ID,
+		Kind:      models.SecurityEventKeyRevoked,
+		Detail:    detail,
+		At:        at,
+	}); err != nil {
+		h.logger().Warn("failed to record key rotation", "channel_id", channel.ID, "error", err)
+	}
+}
+
+// listSecurityEvents serves GET /api/channels/{id}/stream/security-events,
+// newest first. since takes an RFC 3339 time and defaults to 30 days ago.
+func (h *Handler) listSecurityEvents(channel models.Channel, w http.ResponseWriter, r *http.Request) {
+	since := h.now().Add(-securityEventsDefaultWindow)
+	if raw := strings.TrimSpace(r.URL.Query().Get("since")); raw != "" {
+		parsed, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			WriteRequestError(w, ValidationError("since must be an RFC 3339 time"))
+			return
+		}
+		since = parsed
+	}
+	events, err := h.Store.ListSecurityEvents(channel.ID, since)
+	if err != nil {
+		writeStorageError(w, http.StatusInternalServerError, err)
+		return
+	}
+	response := make([]securityEventResponse, 0, len(events))
+	for _, event := range events {
+		response = append(response, newSecurityEventResponse(event))
+	}
+	WriteJSON(w, http.StatusOK, response)
+}
@@ -0,0 +1,312 @@
+package api
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+func newStreamKeySecurityFixture(t *testing.T) (*Handler, *storage.Storage, models.User, models.Channel, *bytes.Buffer) {
+	t.Helper()
+	handler, store := newTestHandler(t)
+	handler.SRSHookToken = "secret"
+	var audit bytes.Buffer
+	handler.AuditLogger = slog.New(slog.NewJSONHandler(&audit, nil))
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Guarded", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	return handler, store, owner, channel, &audit
+}
+
+func srsPublish(t *testing.T, handler *Handler, streamKey, ip string) *httptest.ResponseRecorder {
+	t.Helper()
+	body, _ := json.Marshal(srsHookRequest{Action: "on_publish", Stream: streamKey, IP: ip})
+	req := httptest.NewRequest(http.MethodPost, "/api/ingest/srs-hook?token=secret", bytes.NewReader(body))
+	rec := httptest.NewRecorder()
+	handler.SRSHook(rec, req)
+	return rec
+}
+
+func TestSRSPublishRejectsSecondSourceWhileLive(t *testing.T) {
+	handler, store, owner, channel, audit := newStreamKeySecurityFixture(t)
+
+	if rec := srsPublish(t, handler, channel.StreamKey, "203.0.113.7"); rec.Code != http.StatusOK {
+		t.Fatalf("expected first publish to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	session, ok := store.CurrentStreamSession(channel.ID)
+	if !ok || session.PublishSource != "203.0.113.7" {
+		t.Fatalf("expected the session to record its publish source, got %+v", session)
+	}
+
+	rec := srsPublish(t, handler, channel.StreamKey, "198.51.100.9:50123")
+	if rec.Code != http.StatusForbidden || !strings.Contains(rec.Body.String(), "publish_source_conflict") {
+		t.Fatalf("expected a publish from a second source to be rejected, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if current, ok := store.CurrentStreamSession(channel.ID); !ok || current.ID != session.ID || current.PublishSource != "203.0.113.7" {
+		t.Fatalf("expected the original session to keep publishing, got %+v", current)
+	}
+
+	events, err := store.ListSecurityEvents(channel.ID, time.Time{})
+	if err != nil {
+		t.Fatalf("ListSecurityEvents: %v", err)
+	}
+	if len(events) != 1 || events[0].Kind != models.SecurityEventPublishRejected || events[0].SourceIP != "198.51.100.9" || events[0].SessionID != session.ID {
+		t.Fatalf("expected a rejected publish event, got %+v", events)
+	}
+	notifications, err := store.ListNotifications(owner.ID, 0)
+	if err != nil {
+		t.Fatalf("ListNotifications: %v", err)
+	}
+	if len(notifications) != 1 {
+		t.Fatalf("expected the owner to be notified once, got %+v", notifications)
+	}
+	notice := notifications[0]
+	if notice.Category != models.NotificationCategoryAccount || notice.Kind != models.SecurityEventPublishRejected || notice.Metadata["sourceIp"] != "198.51.100.9" || notice.Metadata["at"] == "" {
+		t.Fatalf("unexpected notification %+v", notice)
+	}
+	if !strings.Contains(notice.Message, "198.51.100.9") {
+		t.Fatalf("expected the notice to name the source, got %q", notice.Message)
+	}
+	logged := audit.String()
+	if !strings.Contains(logged, `"action":"stream_key.publish_rejected"`) || !strings.Contains(logged, `"source_ip":"198.51.100.9"`) || !strings.Contains(logged, `"at":`) {
+		t.Fatalf("expected the rejection in the audit log, got %s", logged)
+	}
+
+	// The original encoder reconnecting is not a leak.
+	if rec := srsPublish(t, handler, channel.StreamKey, "203.0.113.7"); rec.Code != http.StatusOK {
+		t.Fatalf("expected the original source to reconnect, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestSecurityNoticesRespectNotificationPreferences(t *testing.T) {
+	handler, store, owner, channel, _ := newStreamKeySecurityFixture(t)
+	off := false
+	if _, err := store.UpdateNotificationPreferences(owner.ID, storage.NotificationPreferencesUpdate{Account: &storage.NotificationDeliveryUpdate{InApp: &off}}); err != nil {
+		t.Fatalf("UpdateNotificationPreferences: %v", err)
+	}
+
+	srsPublish(t, handler, channel.StreamKey, "203.0.113.7")
+	if rec := srsPublish(t, handler, channel.StreamKey, "198.51.100.9"); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected the second source to be rejected, got %d", rec.Code)
+	}
+	if events, _ := store.ListSecurityEvents(channel.ID, time.Time{}); len(events) != 1 {
+		t.Fatalf("expected the rejection to be recorded, got %+v", events)
+	}
+	if notifications, _ := store.ListNotifications(owner.ID, 0); len(notifications) != 0 {
+		t.Fatalf("expected no in-app notice with account notifications off, got %+v", notifications)
+	}
+}
+
+func TestIngestEventPublishRejectsSecondSource(t *testing.T) {
+	handler, store, owner, channel, _ := newStreamKeySecurityFixture(t)
+	handler.IngestEventsToken = "events"
+	if _, err := store.StartStream(channel.ID, []string{"720p"}); err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+
+	send := func(clientIP string) *httptest.ResponseRecorder {
+		body, _ := json.Marshal(ingestEventRequest{Event: "publish", StreamKey: channel.StreamKey, ClientIP: clientIP})
+		req := httptest.NewRequest(http.MethodPost, "/api/internal/ingest/events?token=events", bytes.NewReader(body))
+		rec := httptest.NewRecorder()
+		handler.IngestEvents(rec, req)
+		return rec
+	}
+	if rec := send("2001:db8::1"); rec.Code != http.StatusOK {
+		t.Fatalf("expected first publish to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := send("2001:db8:1::1"); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected a second source to be rejected, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if notifications, _ := store.ListNotifications(owner.ID, 0); len(notifications) != 1 {
+		t.Fatalf("expected the owner to be notified, got %+v", notifications)
+	}
+}
+
+func TestRotateAndStopStream(t *testing.T) {
+	handler, store, owner, channel, audit := newStreamKeySecurityFixture(t)
+	if rec := srsPublish(t, handler, channel.StreamKey, "203.0.113.7"); rec.Code != http.StatusOK {
+		t.Fatalf("publish: %d", rec.Code)
+	}
+	live, _ := store.CurrentStreamSession(channel.ID)
+
+	req := withUser(httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/stream/rotate-and-stop", nil), owner)
+	rec := httptest.NewRecorder()
+	handler.ChannelByID(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp rotateAndStopResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Channel.StreamKey == "" || resp.Channel.StreamKey == channel.StreamKey {
+		t.Fatalf("expected a new stream key, got %q", resp.Channel.StreamKey)
+	}
+	if resp.Session == nil || resp.Session.ID != live.ID || resp.Session.EndedAt == nil || resp.StopError != "" {
+		t.Fatalf("expected the live session to be stopped, got %+v", resp)
+	}
+	if _, ok := store.CurrentStreamSession(channel.ID); ok {
+		t.Fatal("expected the channel to be offline")
+	}
+	if rec := srsPublish(t, handler, channel.StreamKey, "198.51.100.9"); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected the leaked key to stop working, got %d", rec.Code)
+	}
+	events, err := store.ListSecurityEvents(channel.ID, time.Time{})
+	if err != nil {
+		t.Fatalf("ListSecurityEvents: %v", err)
+	}
+	if len(events) != 1 || events[0].Kind != models.SecurityEventKeyRevoked {
+		t.Fatalf("expected the rotation to be recorded, got %+v", events)
+	}
+	if !strings.Contains(audit.String(), `"action":"stream_key.rotate_and_stop"`) {
+		t.Fatalf("expected the action in the audit log, got %s", audit.String())
+	}
+
+	// An offline channel still gets a new key.
+	req = withUser(httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/stream/rotate-and-stop", nil), owner)
+	rec = httptest.NewRecorder()
+	handler.ChannelByID(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 while offline, got %d: %s", rec.Code, rec.Body.String())
+	}
+	resp = rotateAndStopResponse{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Session != nil || resp.StopError != "" {
+		t.Fatalf("expected no session while offline, got %+v", resp)
+	}
+}
+
+func TestKeySourceSpreadFlagsChannel(t *testing.T) {
+	handler, store, owner, channel, audit := newStreamKeySecurityFixture(t)
+	handler.KeyLeakSourceLimit = 2
+
+	stream := func(ip string) {
+		t.Helper()
+		if rec := srsPublish(t, handler, channel.StreamKey, ip); rec.Code != http.StatusOK {
+			t.Fatalf("publish from %s: %d %s", ip, rec.Code, rec.Body.String())
+		}
+		if _, err := store.StopStream(channel.ID, 0); err != nil {
+			t.Fatalf("StopStream: %v", err)
+		}
+	}
+	spread := func() []models.SecurityEvent {
+		t.Helper()
+		events, err := store.ListSecurityEvents(channel.ID, time.Time{})
+		if err != nil {
+			t.Fatalf("ListSecurityEvents: %v", err)
+		}
+		var flagged []models.SecurityEvent
+		for _, event := range events {
+			if event.Kind == models.SecurityEventKeySourcesSpread {
+				flagged = append(flagged, event)
+			}
+		}
+		return flagged
+	}
+
+	// Two addresses in one /24 count as a single network.
+	stream("203.0.113.7")
+	stream("203.0.113.99")
+	stream("198.51.100.9")
+	if flagged := spread(); len(flagged) != 0 {
+		t.Fatalf("expected two networks to stay under the limit, got %+v", flagged)
+	}
+	stream("192.0.2.44")
+	if flagged := spread(); len(flagged) != 1 {
+		t.Fatalf("expected a third network to flag the key, got %+v", flagged)
+	}
+	stream("100.64.0.1")
+	if flagged := spread(); len(flagged) != 1 {
+		t.Fatalf("expected the channel to be flagged once per window, got %+v", flagged)
+	}
+	notifications, err := store.ListNotifications(owner.ID, 0)
+	if err != nil {
+		t.Fatalf("ListNotifications: %v", err)
+	}
+	if len(notifications) != 1 || notifications[0].Kind != models.SecurityEventKeySourcesSpread {
+		t.Fatalf("expected one spread notice, got %+v", notifications)
+	}
+	if !strings.Contains(audit.String(), `"action":"stream_key.sources_spread"`) {
+		t.Fatalf("expected the flag in the audit log, got %s", audit.String())
+	}
+}
+
+func TestKeySourceSpreadCountsOnlyCurrentKey(t *testing.T) {
+	handler, store, owner, channel, _ := newStreamKeySecurityFixture(t)
+	handler.KeyLeakSourceLimit = 1
+
+	if rec := srsPublish(t, handler, channel.StreamKey, "203.0.113.7"); rec.Code != http.StatusOK {
+		t.Fatalf("publish: %d", rec.Code)
+	}
+	req := withUser(httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/stream/rotate", nil), owner)
+	rec := httptest.NewRecorder()
+	handler.ChannelByID(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("rotate: %d %s", rec.Code, rec.Body.String())
+	}
+	var rotated channelResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &rotated); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if _, err := store.StopStream(channel.ID, 0); err != nil {
+		t.Fatalf("StopStream: %v", err)
+	}
+	if rec := srsPublish(t, handler, rotated.StreamKey, "198.51.100.9"); rec.Code != http.StatusOK {
+		t.Fatalf("publish with the new key: %d", rec.Code)
+	}
+	events, err := store.ListSecurityEvents(channel.ID, time.Time{})
+	if err != nil {
+		t.Fatalf("ListSecurityEvents: %v", err)
+	}
+	for _, event := range events {
+		if event.Kind == models.SecurityEventKeySourcesSpread {
+			t.Fatalf("expected sessions with the old key not to count, got %+v", events)
+		}
+	}
+}
+
+func TestUserNotificationsListsNewestFirst(t *testing.T) {
+	handler, store, owner, _, _ := newStreamKeySecurityFixture(t)
+	base := time.Now().UTC().Add(-time.Hour)
+	for i, message := range []string{"First", "Second", "Third"} {
+		if _, err := store.CreateNotification(storage.NotificationParams{UserID: owner.ID, Category: models.NotificationCategoryAccount, Kind: "notice", Message: message, At: base.Add(time.Duration(i) * time.Minute)}); err != nil {
+			t.Fatalf("CreateNotification: %v", err)
+		}
+	}
+
+	req := withUser(httptest.NewRequest(http.MethodGet, "/api/users/me/notifications?limit=2", nil), owner)
+	rec := httptest.NewRecorder()
+	handler.UserByID(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var notifications []notificationResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &notifications); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if len(notifications) != 2 || notifications[0].Message != "Third" || notifications[1].Message != "Second" {
+		t.Fatalf("expected the two newest notifications, got %+v", notifications)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/users/me/notifications", nil)
+	rec = httptest.NewRecorder()
+	handler.UserByID(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected anonymous requests to be refused, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,112 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+
+	"bitriver-live/internal/authz"
+	"bitriver-live/internal/storage"
+)
+
+// streamLimitRequest sets a user's concurrent stream limit override. A null
+// maxConcurrentStreams returns the user to the platform default; zero lifts
+// the limit.
+type streamLimitRequest struct {
+	MaxConcurrentStreams *int `json:"maxConcurrentStreams"`
+}
+
+type streamSlotResponse struct {
+	ChannelID string `json:"channelId"`
+	Title     string `json:"title"`
+	LiveState string `json:"liveState"`
+}
+
+// streamSlotsResponse reports a user's concurrent streams. Limit is the
+// limit in force, zero when unlimited; LimitOverride is set when an admin
+// overrode the platform default. Active lists the channels starting or
+// live.
+type streamSlotsResponse struct {
+	UserID        string               `json:"userId,omitempty"`
+	Limit         int                  `json:"limit"`
+	LimitOverride *int                 `json:"limitOverride,omitempty"`
+	Active        []streamSlotResponse `json:"active"`
+}
+
+// streamLimitErrorResponse is the body of a start refused by the owner's
+// concurrent stream limit. It carries the usual error object alongside the
+// channels holding the slots, so clients can offer to stop one.
+type streamLimitErrorResponse struct {
+	Error       apiErrorBody        `json:"error"`
+	StreamLimit streamSlotsResponse `json:"streamLimit"`
+}
+
+func newStreamSlotResponses(slots []storage.StreamSlot) []streamSlotResponse {
+	response := make([]streamSlotResponse, 0, len(slots))
+	for _, slot := range slots {
+		response = append(response, streamSlotResponse{ChannelID: slot.ChannelID, Title: slot.Title, LiveState: slot.LiveState})
+	}
+	return response
+}
+
+func newStreamSlotsResponse(report storage.StreamSlotReport) streamSlotsResponse {
+	return streamSlotsResponse{
+		UserID:        report.UserID,
+		Limit:         report.Limit,
+		LimitOverride: report.LimitOverride,
+		Active:        newStreamSlotResponses(report.Active),
+	}
+}
+
+// writeStreamLimitError writes a 409 stream_limit_reached naming the
+// channels that hold the owner's slots, and returns false for any other
+// error.
+func writeStreamLimitError(w http.ResponseWriter, err error) bool {
+	var limitErr *storage.StreamLimitError
+	if !errors.As(err, &limitErr) {
+		return false
+	}
+	WriteJSON(w, http.StatusConflict, streamLimitErrorResponse{
+		Error: apiErrorBody{Code: "stream_limit_reached", Message: limitErr.Error()},
+		StreamLimit: streamSlotsResponse{
+			Limit:  limitErr.Limit,
+			Active: newStreamSlotResponses(limitErr.Active),
+		},
+	})
+	return true
+}
+
+// adminUserStreamLimit serves /api/admin/users/{id}/stream-limit for
+// holders of users.manage: GET reports the user's concurrent streams and
+// limit, and PUT sets or clears their override.
+func (h *Handler) adminUserStreamLimit(userID string, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPut {
+		WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodPut)
+		return
+	}
+	actor, ok := h.requirePermission(w, r, authz.UsersManage)
+	if !ok {
+		return
+	}
+	if r.Method == http.MethodPut {
+		var req streamLimitRequest
+		if !DecodeAndValidate(w, r, &req) {
+			return
+		}
+		if _, err := h.Store.SetUserStreamLimit(userID, req.MaxConcurrentStreams); err != nil {
+			writeStorageError(w, http.StatusBadRequest, err)
+			return
+		}
+		limit := "default"
+		if req.MaxConcurrentStreams != nil {
+			limit = fmt.Sprint(*req.MaxConcurrentStreams)
+		}
+		h.auditLogger().Info("audit", "action", "user.stream_limit", "actor_id", actor.ID, "user_id", userID, "max_concurrent_streams", limit)
+	}
+	report, err := h.Store.StreamSlotUsage(userID)
+	if err != nil {
+		writeStorageError(w, http.StatusInternalServerError, err)
+		return
+	}
+	WriteJSON(w, http.StatusOK, newStreamSlotsResponse(report))
+}
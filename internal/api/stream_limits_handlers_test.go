@@ -0,0 +1,123 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+func TestStartStreamReportsTheChannelHoldingTheSlot(t *testing.T) {
+	handler, store := newTestHandler(t)
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Creator", Email: "creator@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	first, err := store.CreateChannel(owner.ID, "Main Show", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	second, err := store.CreateChannel(owner.ID, "Side Show", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	one := 1
+	if _, err := store.SetUserStreamLimit(owner.ID, &one); err != nil {
+		t.Fatalf("SetUserStreamLimit: %v", err)
+	}
+
+	start := func(channelID string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(http.MethodPost, "/api/channels/"+channelID+"/stream/start", strings.NewReader(`{"renditions":["720p"]}`)), owner)
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		return rec
+	}
+	if rec := start(first.ID); rec.Code != http.StatusCreated {
+		t.Fatalf("expected the first start to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec := start(second.ID)
+	if rec.Code != http.StatusConflict {
+		t.Fatalf("expected the second start refused, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var payload streamLimitErrorResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+		t.Fatalf("decode error: %v", err)
+	}
+	if payload.Error.Code != "stream_limit_reached" || !strings.Contains(payload.Error.Message, "Main Show") {
+		t.Fatalf("unexpected error %+v", payload.Error)
+	}
+	if payload.StreamLimit.Limit != 1 || len(payload.StreamLimit.Active) != 1 || payload.StreamLimit.Active[0].ChannelID != first.ID || payload.StreamLimit.Active[0].LiveState != "live" {
+		t.Fatalf("expected the payload to name the live channel, got %+v", payload.StreamLimit)
+	}
+}
+
+func TestAdminUserStreamLimit(t *testing.T) {
+	handler, store := newTestHandler(t)
+	admin, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Admin", Email: "admin@example.com", Roles: []string{"admin"}})
+	if err != nil {
+		t.Fatalf("CreateUser admin: %v", err)
+	}
+	creator, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Creator", Email: "creator@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(creator.ID, "Main Show", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	if _, err := store.StartStream(channel.ID, []string{"720p"}); err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+
+	path := "/api/admin/users/" + creator.ID + "/stream-limit"
+	cases := []struct {
+		name   string
+		actor  models.User
+		method string
+		path   string
+		body   string
+		status int
+	}{
+		{"creator cannot read", creator, http.MethodGet, path, "", http.StatusForbidden},
+		{"creator cannot raise their own limit", creator, http.MethodPut, path, `{"maxConcurrentStreams":5}`, http.StatusForbidden},
+		{"negative limit", admin, http.MethodPut, path, `{"maxConcurrentStreams":-1}`, http.StatusBadRequest},
+		{"missing user", admin, http.MethodPut, "/api/admin/users/ghost/stream-limit", `{"maxConcurrentStreams":2}`, http.StatusNotFound},
+		{"wrong method", admin, http.MethodPost, path, `{}`, http.StatusMethodNotAllowed},
+	}
+	for _, tc := range cases {
+		if rec := serveBadges(t, handler.AdminUserByID, tc.actor, tc.method, tc.path, tc.body); rec.Code != tc.status {
+			t.Fatalf("%s: expected %d, got %d: %s", tc.name, tc.status, rec.Code, rec.Body.String())
+		}
+	}
+
+	decode := func(rec *httptest.ResponseRecorder) streamSlotsResponse {
+		t.Helper()
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var payload streamSlotsResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &payload); err != nil {
+			t.Fatalf("decode stream limit: %v", err)
+		}
+		return payload
+	}
+	report := decode(serveBadges(t, handler.AdminUserByID, admin, http.MethodGet, path, ""))
+	if report.Limit != 0 || report.LimitOverride != nil || len(report.Active) != 1 || report.Active[0].ChannelID != channel.ID {
+		t.Fatalf("expected an unlimited user with one live channel, got %+v", report)
+	}
+	report = decode(serveBadges(t, handler.AdminUserByID, admin, http.MethodPut, path, `{"maxConcurrentStreams":3}`))
+	if report.Limit != 3 || report.LimitOverride == nil || *report.LimitOverride != 3 {
+		t.Fatalf("expected the override applied, got %+v", report)
+	}
+	if stored, ok := store.GetUser(creator.ID); !ok || stored.MaxConcurrentStreams == nil || *stored.MaxConcurrentStreams != 3 {
+		t.Fatalf("expected the override on the user, got %+v", stored.MaxConcurrentStreams)
+	}
+	report = decode(serveBadges(t, handler.AdminUserByID, admin, http.MethodPut, path, `{"maxConcurrentStreams":null}`))
+	if report.Limit != 0 || report.LimitOverride != nil {
+		t.Fatalf("expected the override cleared, got %+v", report)
+	}
+}
@@ -0,0 +1,121 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"bitriver-live/internal/chat"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+type streamMarkerRequest struct {
+	Label string `json:"label"`
+}
+
+type streamMarkerResponse struct {
+	ID            string `json:"id"`
+	SessionID     string `json:"sessionId"`
+	Label         string `json:"label"`
+	OffsetSeconds int    `json:"offsetSeconds"`
+	CreatedBy     string `json:"createdBy,omitempty"`
+	CreatedAt     string `json:"createdAt"`
+}
+
+func newStreamMarkerResponse(marker models.StreamMarker) streamMarkerResponse {
+	return streamMarkerResponse{
+		ID:            marker.ID,
+		SessionID:     marker.SessionID,
+		Label:         marker.Label,
+		OffsetSeconds: marker.OffsetSeconds,
+		CreatedBy:     marker.CreatedBy,
+		CreatedAt:     marker.CreatedAt.Format(time.RFC3339Nano),
+	}
+}
+
+// handleStreamMarkers serves /api/channels/{id}/stream/markers. The channel
+// owner and moderators mark moments of the live session with POST and list
+// the session's markers with GET; both answer 409 while the channel is
+// offline. The offset is taken from the server clock, not the request.
+func (h *Handler) handleStreamMarkers(channel models.Channel, w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodPost {
+		WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodPost)
+		return
+	}
+	actor, ok := h.requireAuthenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	if !h.canModerateChannel(actor, channel) {
+		WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
+		return
+	}
+
+	if r.Method == http.MethodGet {
+		if channel.CurrentSessionID == nil {
+			WriteRequestError(w, channelOfflineError(channel, nil))
+			return
+		}
+		markers, err := h.Store.ListStreamMarkers(*channel.CurrentSessionID)
+		if err != nil {
+			writeStorageError(w, http.StatusInternalServerError, err)
+			return
+		}
+		items := make([]streamMarkerResponse, 0, len(markers))
+		for _, marker := range markers {
+			items = append(items, newStreamMarkerResponse(marker))
+		}
+		WriteJSON(w, http.StatusOK, items)
+		return
+	}
+
+	var req streamMarkerRequest
+	if !DecodeAndValidate(w, r, &req) {
+		return
+	}
+	marker, err := h.Store.CreateStreamMarker(channel.ID, storage.StreamMarkerParams{
+		UserID: actor.ID,
+		Label:  req.Label,
+		At:     h.now(),
+	})
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrChannelNotLive):
+			WriteRequestError(w, channelOfflineError(channel, err))
+		case errors.Is(err, storage.ErrStreamMarkerLimit):
+			WriteRequestError(w, RequestError{Status: http.StatusConflict, CodeVal: "marker_limit", Message: fmt.Sprintf("a stream can hold at most %d markers", storage.MaxStreamMarkersPerSession), Err: err})
+		default:
+			writeStorageError(w, http.StatusBadRequest, err)
+		}
+		return
+	}
+	if h.ChatGateway != nil {
+		if err := h.ChatGateway.AnnounceMarker(r.Context(), chat.MarkerEvent{
+			ID:            marker.ID,
+			ChannelID:     marker.ChannelID,
+			SessionID:     marker.SessionID,
+			Label:         marker.Label,
+			OffsetSeconds: marker.OffsetSeconds,
+			CreatedAt:     marker.CreatedAt,
+		}); err != nil {
+			h.logger().Warn("announce stream marker", "channel_id", marker.ChannelID, "marker_id", marker.ID, "error", err)
+		}
+	}
+	WriteJSON(w, http.StatusCreated, newStreamMarkerResponse(marker))
+}
+
+// recordingMarkers places the markers of a recording's session on the VOD
+// timeline. Recordings start when their session starts, so offsets carry over
+// unchanged; markers past the end of the recording are dropped.
+func recordingMarkers(recording models.Recording, markers []models.StreamMarker) []streamMarkerResponse {
+	items := make([]streamMarkerResponse, 0, len(markers))
+	for _, marker := range markers {
+		if recording.DurationSeconds > 0 && marker.OffsetSeconds > recording.DurationSeconds {
+			continue
+		}
+		items = append(items, newStreamMarkerResponse(marker))
+	}
+	return items
+}
@@ -0,0 +1,139 @@
+package api
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/auth"
+	"bitriver-live/internal/ingest"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+func TestStreamMarkersFollowTheSessionIntoTheRecording(t *testing.T) {
+	store, err := storage.NewStorage(filepath.Join(t.TempDir(), "store.json"), storage.WithIngestController(bootResultController{result: ingest.BootResult{
+		PrimaryIngest: "rtmp://ingest.example/live",
+		OriginURL:     "https://origin.example/live",
+		PlaybackURL:   "https://cdn.example/master.m3u8",
+		JobIDs:        []string{"job-1"},
+	}}), storage.WithIngestRetries(1, 0))
+	if err != nil {
+		t.Fatalf("NewStorage: %v", err)
+	}
+	handler := NewHandler(store, auth.NewSessionManager(24*time.Hour))
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser owner: %v", err)
+	}
+	viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser viewer: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Marathon", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+
+	markers := func(method string, user models.User, body string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(method, "/api/channels/"+channel.ID+"/stream/markers", strings.NewReader(body)), user)
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		return rec
+	}
+
+	for _, method := range []string{http.MethodGet, http.MethodPost} {
+		rec := markers(method, owner, `{"label":"Offline"}`)
+		if rec.Code != http.StatusConflict || decodeAPIError(t, rec.Body.Bytes()).Error.Code != "channel_offline" {
+			t.Fatalf("%s: expected 409 channel_offline, got %d: %s", method, rec.Code, rec.Body.String())
+		}
+	}
+
+	session, err := store.StartStream(channel.ID, []string{"720p"})
+	if err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	now := session.StartedAt.Add(75 * time.Second)
+	handler.Now = func() time.Time { return now }
+
+	if rec := markers(http.MethodPost, viewer, `{"label":"Not mine"}`); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected 403 for viewers, got %d", rec.Code)
+	}
+	rec := markers(http.MethodPost, owner, `{"label":"Clutch round"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created streamMarkerResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode marker: %v", err)
+	}
+	if created.OffsetSeconds != 75 || created.SessionID != session.ID || created.Label != "Clutch round" {
+		t.Fatalf("expected a marker 75s into session %s, got %+v", session.ID, created)
+	}
+
+	for i := 1; i < storage.MaxStreamMarkersPerSession; i++ {
+		now = now.Add(time.Second)
+		if rec := markers(http.MethodPost, owner, fmt.Sprintf(`{"label":"Marker %d"}`, i)); rec.Code != http.StatusCreated {
+			t.Fatalf("marker %d: expected 201, got %d: %s", i, rec.Code, rec.Body.String())
+		}
+	}
+	rec = markers(http.MethodPost, owner, `{"label":"One too many"}`)
+	if rec.Code != http.StatusConflict || decodeAPIError(t, rec.Body.Bytes()).Error.Code != "marker_limit" {
+		t.Fatalf("expected 409 marker_limit, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	rec = markers(http.MethodGet, owner, "")
+	var listed []streamMarkerResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &listed); err != nil {
+		t.Fatalf("decode markers: %v", err)
+	}
+	if rec.Code != http.StatusOK || len(listed) != storage.MaxStreamMarkersPerSession {
+		t.Fatalf("expected %d markers, got %d: %d", storage.MaxStreamMarkersPerSession, rec.Code, len(listed))
+	}
+
+	if _, err := store.StopStream(channel.ID, 1); err != nil {
+		t.Fatalf("StopStream: %v", err)
+	}
+	// StopStream ends the session on the wall clock, a moment after it
+	// started, while the markers were placed on the handler's clock. Report
+	// the recording as lasting until that clock so no marker falls past its
+	// end.
+	handler.Store = recordingDurationRepo{Repository: store, durationSeconds: int(now.Sub(session.StartedAt).Seconds())}
+	recordings, err := store.ListRecordings(channel.ID, true)
+	if err != nil || len(recordings) != 1 {
+		t.Fatalf("expected one recording, got %d (%v)", len(recordings), err)
+	}
+	req := withUser(httptest.NewRequest(http.MethodGet, "/api/recordings/"+recordings[0].ID, nil), owner)
+	rec = httptest.NewRecorder()
+	handler.RecordingByID(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200 for the recording, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var recording recordingResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &recording); err != nil {
+		t.Fatalf("decode recording: %v", err)
+	}
+	if len(recording.Markers) == 0 || recording.Markers[0].ID != created.ID || recording.Markers[0].OffsetSeconds != 75 {
+		t.Fatalf("expected the recording to start with the 75s marker, got %d markers", len(recording.Markers))
+	}
+}
+
+// recordingDurationRepo reports every recording as lasting durationSeconds,
+// standing in for a session stopped on the handler's clock.
+type recordingDurationRepo struct {
+	storage.Repository
+	durationSeconds int
+}
+
+func (r recordingDurationRepo) GetRecording(id string) (models.Recording, bool) {
+	recording, ok := r.Repository.GetRecording(id)
+	if ok {
+		recording.DurationSeconds = r.durationSeconds
+	}
+	return recording, ok
+}
@@ -0,0 +1,236 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/auth"
+	"bitriver-live/internal/ingest"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+	"bitriver-live/internal/testsupport/ingeststub"
+)
+
+// hangingBootController never finishes booting until released and records
+// the sessions it is asked to shut down.
+type hangingBootController struct {
+	ingest.NoopController
+	booting  chan struct{}
+	release  chan struct{}
+	mu       sync.Mutex
+	shutdown []string
+}
+
+func (c *hangingBootController) BootStream(ctx context.Context, params ingest.BootParams) (ingest.BootResult, error) {
+	c.booting <- struct{}{}
+	<-c.release
+	return ingest.BootResult{}, context.Canceled
+}
+
+func (c *hangingBootController) ShutdownStream(ctx context.Context, channelID, sessionID string, jobIDs []string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.shutdown = append(c.shutdown, sessionID)
+	return nil
+}
+
+func TestStreamRecoverClearsStuckStart(t *testing.T) {
+	controller := &hangingBootController{booting: make(chan struct{}, 1), release: make(chan struct{})}
+	defer close(controller.release)
+	timeout := 50 * time.Millisecond
+	store, err := storage.NewStorage(filepath.Join(t.TempDir(), "store.json"),
+		storage.WithIngestController(controller),
+		storage.WithIngestRetries(1, 0),
+		storage.WithStartingTimeout(timeout),
+	)
+	if err != nil {
+		t.Fatalf("NewStorage: %v", err)
+	}
+	handler := NewHandler(store, auth.NewSessionManager(24*time.Hour))
+	var audit bytes.Buffer
+	handler.AuditLogger = slog.New(slog.NewJSONHandler(&audit, nil))
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Stuck", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(method, "/api/channels/"+channel.ID+path, strings.NewReader(body)), owner)
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		return rec
+	}
+
+	go func() { _, _ = store.StartStream(channel.ID, []string{"720p"}) }()
+	<-controller.booting
+
+	rec := send(http.MethodGet, "", "")
+	var starting channelResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &starting); err != nil {
+		t.Fatalf("decode channel: %v", err)
+	}
+	if starting.LiveState != "starting" || starting.StartingSince == nil {
+		t.Fatalf("expected startingSince on a starting channel, got %s", rec.Body.String())
+	}
+	rec = send(http.MethodPost, "/stream/start", `{"renditions":["720p"]}`)
+	if rec.Code != http.StatusConflict || decodeAPIError(t, rec.Body.Bytes()).Error.Code != "stream_starting" {
+		t.Fatalf("expected 409 stream_starting, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = send(http.MethodPost, "/stream/recover", "")
+	if rec.Code != http.StatusConflict || decodeAPIError(t, rec.Body.Bytes()).Error.Code != "stream_not_stuck" {
+		t.Fatalf("expected 409 stream_not_stuck, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	time.Sleep(timeout)
+	rec = send(http.MethodPost, "/stream/start", `{"renditions":["720p"]}`)
+	if rec.Code != http.StatusConflict || decodeAPIError(t, rec.Body.Bytes()).Error.Code != "stream_start_stuck" {
+		t.Fatalf("expected 409 stream_start_stuck, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = send(http.MethodPost, "/stream/recover", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var recovered streamRecoveryResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &recovered); err != nil {
+		t.Fatalf("decode recovery: %v", err)
+	}
+	if recovered.Channel.LiveState != "offline" || recovered.Channel.StartingSince != nil || recovered.Channel.CurrentSessionID != nil {
+		t.Fatalf("expected an offline channel, got %+v", recovered.Channel)
+	}
+	if starting.CurrentSessionID == nil || recovered.SessionID != *starting.CurrentSessionID {
+		t.Fatalf("expected the stuck session recovered, got %q", recovered.SessionID)
+	}
+	controller.mu.Lock()
+	shutdown := append([]string{}, controller.shutdown...)
+	controller.mu.Unlock()
+	if len(shutdown) != 1 || shutdown[0] != recovered.SessionID {
+		t.Fatalf("expected ingest shut down for session %s, got %v", recovered.SessionID, shutdown)
+	}
+	if !strings.Contains(audit.String(), `"action":"stream.recover"`) || !strings.Contains(audit.String(), recovered.SessionID) {
+		t.Fatalf("expected a stream.recover audit event, got %s", audit.String())
+	}
+}
+
+func TestStreamStartTimeoutAnswers504AndRecovers(t *testing.T) {
+	stub := ingeststub.NewScenario(ingeststub.Options{}).
+		Latency(ingeststub.EndpointJobStart, ingeststub.FixedLatency(5*time.Second)).
+		Start()
+	t.Cleanup(stub.Close)
+	controller, err := ingest.Config{
+		SRSBaseURL:         stub.BaseURL(),
+		SRSToken:           "srs-token",
+		OMEBaseURL:         stub.BaseURL(),
+		OMEUsername:        "ome-user",
+		OMEPassword:        "ome-pass",
+		JobBaseURL:         stub.BaseURL(),
+		JobToken:           "transcoder-token",
+		LadderProfiles:     []ingest.Rendition{{Name: "720p", Bitrate: 2400}},
+		MaxBootAttempts:    1,
+		HTTPMaxAttempts:    1,
+		HealthTimeout:      time.Second,
+		BootCleanupReserve: 50 * time.Millisecond,
+	}.NewHTTPController()
+	if err != nil {
+		t.Fatalf("NewHTTPController: %v", err)
+	}
+	timeout := 100 * time.Millisecond
+	store, err := storage.NewStorage(filepath.Join(t.TempDir(), "store.json"),
+		storage.WithIngestController(controller),
+		storage.WithIngestRetries(3, 0),
+		storage.WithStartingTimeout(timeout),
+	)
+	if err != nil {
+		t.Fatalf("NewStorage: %v", err)
+	}
+	handler := NewHandler(store, auth.NewSessionManager(24*time.Hour))
+	handler.StreamRequestBudget = 300 * time.Millisecond
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Slow", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	send := func(method, path, body string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(method, "/api/channels/"+channel.ID+path, strings.NewReader(body)), owner)
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		return rec
+	}
+
+	start := time.Now()
+	rec := send(http.MethodPost, "/stream/start", `{"renditions":["720p"]}`)
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the start to answer within its budget, took %v", elapsed)
+	}
+	if rec.Code != http.StatusGatewayTimeout || decodeAPIError(t, rec.Body.Bytes()).Error.Code != "stream_start_pending" {
+		t.Fatalf("expected 504 stream_start_pending, got %d: %s", rec.Code, rec.Body.String())
+	}
+	// The boot was cut short, not retried, and the channel is left starting
+	// for recovery to reconcile.
+	if starts := stub.Journal(ingeststub.EndpointJobStart); len(starts) != 1 {
+		t.Fatalf("expected a single boot attempt, got %d job starts", len(starts))
+	}
+	pending, ok := store.GetChannel(channel.ID)
+	if !ok || pending.LiveState != "starting" || pending.CurrentSessionID == nil {
+		t.Fatalf("expected the channel to stay starting, got %+v", pending)
+	}
+
+	time.Sleep(timeout)
+	deletesBefore := len(stub.Journal(ingeststub.EndpointChannelDelete))
+	rec = send(http.MethodPost, "/stream/recover", "")
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var recovered streamRecoveryResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &recovered); err != nil {
+		t.Fatalf("decode recovery: %v", err)
+	}
+	if recovered.SessionID != *pending.CurrentSessionID || recovered.Channel.LiveState != "offline" || recovered.IngestError != "" {
+		t.Fatalf("expected the pending session recovered cleanly, got %+v", recovered)
+	}
+	if deletes := stub.Journal(ingeststub.EndpointChannelDelete); len(deletes) != deletesBefore+1 {
+		t.Fatalf("expected recovery to tear the half-booted channel down, got %+v", deletes)
+	}
+}
+
+func TestStreamRecoverRequiresChannelManager(t *testing.T) {
+	handler, store := newTestHandler(t)
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Guarded", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	for _, tc := range []struct {
+		user models.User
+		want int
+	}{{viewer, http.StatusForbidden}, {owner, http.StatusConflict}} {
+		req := withUser(httptest.NewRequest(http.MethodPost, "/api/channels/"+channel.ID+"/stream/recover", nil), tc.user)
+		rec := httptest.NewRecorder()
+		handler.ChannelByID(rec, req)
+		if rec.Code != tc.want {
+			t.Fatalf("%s: expected %d, got %d: %s", tc.user.DisplayName, tc.want, rec.Code, rec.Body.String())
+		}
+	}
+}
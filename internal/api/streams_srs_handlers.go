@@ -1,15 +1,17 @@
 package api
 
 import (
+	"context"
 	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"strings"
 	"sync"
+	"time"
 
+	"bitriver-live/internal/ingest"
 	"bitriver-live/internal/models"
-	"bitriver-live/internal/observability/metrics"
 	"bitriver-live/internal/storage"
 )
 
@@ -19,16 +21,19 @@ func normalizeSRSAction(action string) string {
 	return normalized
 }
 
-func (h *Handler) channelForStream(stream string) (models.Channel, bool) {
+// channelForStream resolves the channel an SRS hook refers to. Publishing
+// requires the stream key, which is matched against the stored digest in
+// constant time; viewer callbacks may also reference the channel by ID.
+func (h *Handler) channelForStream(stream string, allowChannelID bool) (models.Channel, bool) {
 	trimmed := strings.TrimSpace(stream)
 	if trimmed == "" || h.Store == nil {
 		return models.Channel{}, false
 	}
-	channels := h.Store.ListChannels("", "")
-	for _, channel := range channels {
-		if channel.StreamKey == trimmed || channel.ID == trimmed {
-			return channel, true
-		}
+	if channel, ok := h.Store.FindChannelByStreamKeyHash(storage.HashStreamKey(trimmed)); ok && storage.StreamKeyMatches(channel, trimmed) {
+		return channel, true
+	}
+	if allowChannelID {
+		return h.Store.GetChannel(trimmed)
 	}
 	return models.Channel{}, false
 }
@@ -38,6 +43,9 @@ type srsHookRequest struct {
 	Stream   string `json:"stream"`
 	ClientID string `json:"client_id,omitempty"`
 	Param    string `json:"param,omitempty"`
+	// IP is the address of the client behind the callback, the encoder for
+	// publishes.
+	IP string `json:"ip,omitempty"`
 }
 
 type srsViewerTracker struct {
@@ -77,6 +85,12 @@ func (t *srsViewerTracker) decrement(channelID string) viewerCount {
 	return counts
 }
 
+func (t *srsViewerTracker) current(channelID string) int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.entries[channelID].current
+}
+
 func (t *srsViewerTracker) peak(channelID string) int {
 	t.mu.Lock()
 	defer t.mu.Unlock()
@@ -124,7 +138,7 @@ func (h *Handler) SRSHook(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	channel, ok := h.channelForStream(req.Stream)
+	channel, ok := h.channelForStream(req.Stream, action != "publish")
 	if !ok {
 		if logger := h.logger(); logger != nil {
 			logger.Warn("srs hook stream rejected", "stream", strings.TrimSpace(req.Stream), "action", action)
@@ -137,55 +151,88 @@ func (h *Handler) SRSHook(w http.ResponseWriter, r *http.Request) {
 
 	switch action {
 	case "publish":
-		h.handleSRSPublish(channel, w, r)
+		h.handleSRSPublish(channel, publishSourceIP(req.IP), w, r)
 	case "play":
 		counts := tracker.increment(channel.ID)
+		if counts.current == counts.peak {
+			h.recordSessionPeak(channel.ID, counts.peak)
+		}
 		WriteJSON(w, http.StatusOK, map[string]int{"currentViewers": counts.current})
 	case "stop":
 		counts := tracker.decrement(channel.ID)
 		WriteJSON(w, http.StatusOK, map[string]int{"currentViewers": counts.current})
 	case "unpublish":
 		peak := tracker.peak(channel.ID)
-		h.handleSRSUnpublish(channel, peak, tracker, w)
+		h.handleSRSUnpublish(r.Context(), channel, peak, tracker, w)
 	default:
 		WriteError(w, http.StatusBadRequest, fmt.Errorf("unknown action %s", req.Action))
 	}
 }
 
-func (h *Handler) handleSRSPublish(channel models.Channel, w http.ResponseWriter, r *http.Request) {
+func (h *Handler) handleSRSPublish(channel models.Channel, source string, w http.ResponseWriter, r *http.Request) {
 	if current, ok := h.Store.CurrentStreamSession(channel.ID); ok {
+		// The encoder has connected to a session provisioned beforehand, or
+		// another encoder is publishing with the same key.
+		if !h.claimPublishSource(channel, source, w) {
+			return
+		}
+		if _, err := h.Store.RecordStreamMedia(channel.ID, true, h.now()); err != nil {
+			h.logger().Warn("failed to record stream media", "channel_id", channel.ID, "error", err)
+		}
+		h.invalidateChannelCache(r.Context(), channel.ID)
 		WriteJSON(w, http.StatusOK, srsHookResponse{Status: "ok", Action: "on_publish", ChannelID: channel.ID, SessionID: current.ID})
 		return
 	}
 
-	session, err := h.Store.StartStream(channel.ID, h.srsRenditions())
+	ctx, cancel := context.WithTimeout(r.Context(), h.streamRequestBudget())
+	defer cancel()
+	session, err := h.Store.StartStreamContext(ctx, channel.ID, h.srsRenditions())
 	if err != nil {
+		if writeStreamLimitError(w, err) {
+			return
+		}
+		if reqErr, ok := streamTimeout(err); ok {
+			WriteRequestError(w, reqErr)
+			return
+		}
 		status := http.StatusBadRequest
-		if errors.Is(err, storage.ErrIngestControllerUnavailable) {
+		if errors.Is(err, storage.ErrIngestControllerUnavailable) || errors.Is(err, ingest.ErrTranscoderOutOfStorage) {
 			status = http.StatusServiceUnavailable
 		}
-		WriteError(w, status, err)
+		writeStorageError(w, status, err)
 		return
 	}
-	metrics.StreamStarted()
+	if !h.claimPublishSource(channel, source, w) {
+		return
+	}
+	if _, err := h.Store.RecordStreamMedia(channel.ID, true, h.now()); err != nil {
+		h.logger().Warn("failed to record stream media", "channel_id", channel.ID, "error", err)
+	}
+	h.invalidateChannelCache(r.Context(), channel.ID)
 	WriteJSON(w, http.StatusOK, srsHookResponse{Status: "ok", Action: "on_publish", ChannelID: channel.ID, SessionID: session.ID})
 }
 
-func (h *Handler) handleSRSUnpublish(channel models.Channel, peak int, tracker *srsViewerTracker, w http.ResponseWriter) {
+func (h *Handler) handleSRSUnpublish(ctx context.Context, channel models.Channel, peak int, tracker *srsViewerTracker, w http.ResponseWriter) {
 	if _, ok := h.Store.CurrentStreamSession(channel.ID); ok {
-		session, err := h.Store.StopStream(channel.ID, peak)
+		stopCtx, cancel := context.WithTimeout(ctx, h.streamRequestBudget())
+		defer cancel()
+		session, err := h.Store.StopStreamContext(stopCtx, channel.ID, peak)
 		if err != nil {
+			if reqErr, ok := streamTimeout(err); ok {
+				WriteRequestError(w, reqErr)
+				return
+			}
 			status := http.StatusBadRequest
 			if errors.Is(err, storage.ErrIngestControllerUnavailable) {
 				status = http.StatusServiceUnavailable
 			}
-			WriteError(w, status, err)
+			writeStorageError(w, status, err)
 			return
 		}
 		if tracker != nil {
 			tracker.clear(channel.ID)
 		}
-		metrics.StreamStopped()
+		h.invalidateChannelCache(ctx, channel.ID)
 		WriteJSON(w, http.StatusOK, newSessionResponse(session))
 		return
 	}
@@ -196,9 +243,10 @@ func (h *Handler) handleSRSUnpublish(channel models.Channel, peak int, tracker *
 
 	offline := "offline"
 	if _, err := h.Store.UpdateChannel(channel.ID, storage.ChannelUpdate{LiveState: &offline}); err != nil {
-		WriteError(w, http.StatusBadRequest, err)
+		writeStorageError(w, http.StatusBadRequest, err)
 		return
 	}
+	h.invalidateChannelCache(ctx, channel.ID)
 	WriteJSON(w, http.StatusOK, map[string]string{"status": "ok"})
 }
 
@@ -207,6 +255,8 @@ type startStreamRequest struct {
 }
 
 type stopStreamRequest struct {
+	// PeakConcurrent is deprecated: the session peak is tracked from
+	// viewer callbacks. A value is only applied as a floor.
 	PeakConcurrent int `json:"peakConcurrent"`
 }
 
@@ -223,16 +273,45 @@ type renditionManifestResponse struct {
 	Bitrate     int    `json:"bitrate,omitempty"`
 }
 
+type playbackEndpointResponse struct {
+	Protocol string `json:"protocol"`
+	URL      string `json:"url"`
+	Priority int    `json:"priority"`
+}
+
+func newPlaybackEndpointResponses(endpoints []models.PlaybackEndpoint) []playbackEndpointResponse {
+	if len(endpoints) == 0 {
+		return nil
+	}
+	resp := make([]playbackEndpointResponse, 0, len(endpoints))
+	for _, endpoint := range endpoints {
+		resp = append(resp, playbackEndpointResponse{Protocol: endpoint.Protocol, URL: endpoint.URL, Priority: endpoint.Priority})
+	}
+	return resp
+}
+
 func (h *Handler) handleStreamRoutes(channel models.Channel, remaining []string, w http.ResponseWriter, r *http.Request) {
 	if len(remaining) == 0 {
 		WriteError(w, http.StatusNotFound, fmt.Errorf("stream action missing"))
 		return
 	}
-	if _, ok := h.ensureChannelAccess(w, r, channel); !ok {
+	if remaining[0] == "markers" {
+		if len(remaining) > 1 {
+			WriteError(w, http.StatusNotFound, fmt.Errorf("unknown stream action"))
+			return
+		}
+		// Moderators can mark moments too, so markers skip the owner check.
+		h.handleStreamMarkers(channel, w, r)
+		return
+	}
+	actor, ok := h.ensureChannelAccess(w, r, channel)
+	if !ok {
 		return
 	}
 	action := remaining[0]
 	switch action {
+	case "restreams":
+		h.writeRestreamStatus(channel, w, r)
 	case "start":
 		if r.Method != http.MethodPost {
 			WriteMethodNotAllowed(w, r, http.MethodPost)
@@ -242,16 +321,38 @@ func (h *Handler) handleStreamRoutes(channel models.Channel, remaining []string,
 		if !DecodeAndValidate(w, r, &req) {
 			return
 		}
-		session, err := h.Store.StartStream(channel.ID, req.Renditions)
+		if err := h.checkRequestedLadder(channel, req.Renditions); err != nil {
+			WriteRequestError(w, transcodeLimitRequestError(err))
+			return
+		}
+		ctx, cancel := context.WithTimeout(r.Context(), h.streamRequestBudget())
+		defer cancel()
+		session, err := h.Store.StartStreamContext(ctx, channel.ID, req.Renditions)
 		if err != nil {
+			var limitErr *ingest.TranscodeLimitError
+			if errors.As(err, &limitErr) {
+				WriteRequestError(w, transcodeLimitRequestError(limitErr))
+				return
+			}
+			if writeStreamLimitError(w, err) {
+				return
+			}
+			if reqErr, ok := streamStartConflict(err); ok {
+				WriteRequestError(w, reqErr)
+				return
+			}
+			if reqErr, ok := streamTimeout(err); ok {
+				WriteRequestError(w, reqErr)
+				return
+			}
 			status := http.StatusBadRequest
-			if errors.Is(err, storage.ErrIngestControllerUnavailable) {
+			if errors.Is(err, storage.ErrIngestControllerUnavailable) || errors.Is(err, ingest.ErrTranscoderOutOfStorage) {
 				status = http.StatusServiceUnavailable
 			}
-			WriteError(w, status, err)
+			writeStorageError(w, status, err)
 			return
 		}
-		metrics.StreamStarted()
+		h.invalidateChannelCache(r.Context(), channel.ID)
 		WriteJSON(w, http.StatusCreated, newSessionResponse(session))
 	case "stop":
 		if r.Method != http.MethodPost {
@@ -262,16 +363,25 @@ func (h *Handler) handleStreamRoutes(channel models.Channel, remaining []string,
 		if !DecodeAndValidate(w, r, &req) {
 			return
 		}
-		session, err := h.Store.StopStream(channel.ID, req.PeakConcurrent)
+		ctx, cancel := context.WithTimeout(r.Context(), h.streamRequestBudget())
+		defer cancel()
+		session, err := h.Store.StopStreamContext(ctx, channel.ID, max(req.PeakConcurrent, h.srsTracker().peak(channel.ID)))
 		if err != nil {
+			if reqErr, ok := streamTimeout(err); ok {
+				WriteRequestError(w, reqErr)
+				return
+			}
 			status := http.StatusBadRequest
 			if errors.Is(err, storage.ErrIngestControllerUnavailable) {
 				status = http.StatusServiceUnavailable
 			}
-			WriteError(w, status, err)
+			writeStorageError(w, status, err)
 			return
 		}
-		metrics.StreamStopped()
+		// The encoder may stay connected across a stop and restart, in which
+		// case SRS never reports an unpublish, so drop the peak here.
+		h.srsTracker().clear(channel.ID)
+		h.invalidateChannelCache(r.Context(), channel.ID)
 		WriteJSON(w, http.StatusOK, newSessionResponse(session))
 	case "rotate":
 		if r.Method != http.MethodPost {
@@ -280,14 +390,209 @@ func (h *Handler) handleStreamRoutes(channel models.Channel, remaining []string,
 		}
 		updated, err := h.Store.RotateChannelStreamKey(channel.ID)
 		if err != nil {
-			WriteError(w, http.StatusBadRequest, err)
+			writeStorageError(w, http.StatusBadRequest, err)
 			return
 		}
+		h.recordKeyRevoked(channel, false, h.now())
+		h.invalidateChannelCache(r.Context(), channel.ID)
 		WriteJSON(w, http.StatusOK, newChannelResponse(updated))
+	case "rotate-and-stop":
+		if r.Method != http.MethodPost {
+			WriteMethodNotAllowed(w, r, http.MethodPost)
+			return
+		}
+		h.rotateKeyAndStopStream(actor, channel, w, r)
+	case "security-events":
+		if r.Method != http.MethodGet {
+			WriteMethodNotAllowed(w, r, http.MethodGet)
+			return
+		}
+		h.listSecurityEvents(channel, w, r)
+	case "recover":
+		if r.Method != http.MethodPost {
+			WriteMethodNotAllowed(w, r, http.MethodPost)
+			return
+		}
+		h.recoverStream(actor, channel, w, r)
 	default:
 		WriteError(w, http.StatusNotFound, fmt.Errorf("unknown stream action %s", action))
 	}
 }
 
+// streamStartConflict maps the errors StartStream returns while the channel
+// is held by another start.
+func streamStartConflict(err error) (RequestError, bool) {
+	switch {
+	case errors.Is(err, storage.ErrStreamStarting):
+		return RequestError{Status: http.StatusConflict, CodeVal: "stream_starting", Message: "the stream is already starting; try again shortly", Err: err}, true
+	case errors.Is(err, storage.ErrStreamStartStuck):
+		return RequestError{Status: http.StatusConflict, CodeVal: "stream_start_stuck", Message: "a previous start never finished; recover the stream before starting again", Err: err}, true
+	case errors.Is(err, storage.ErrStreamStartAborted):
+		return RequestError{Status: http.StatusConflict, CodeVal: "stream_start_aborted", Message: "the stream was recovered while it was starting", Err: err}, true
+	}
+	return RequestError{}, false
+}
+
+// streamTimeout maps a start or stop that outlived the request budget to a
+// 504. A start cut short leaves the channel starting until it is recovered;
+// a stop cut short leaves it live so the stop can be retried.
+func streamTimeout(err error) (RequestError, bool) {
+	switch {
+	case errors.Is(err, storage.ErrStreamStartTimedOut):
+		return RequestError{Status: http.StatusGatewayTimeout, CodeVal: "stream_start_pending", Message: "ingest did not finish booting in time; the stream may still be settling, recover it if it stays starting", Err: err}, true
+	case errors.Is(err, storage.ErrStreamStopTimedOut):
+		return RequestError{Status: http.StatusGatewayTimeout, CodeVal: "stream_stop_pending", Message: "ingest did not finish shutting down in time; try stopping the stream again", Err: err}, true
+	}
+	return RequestError{}, false
+}
+
+// streamRequestBudget is how long a stream start or stop may wait on
+// ingest.
+func (h *Handler) streamRequestBudget() time.Duration {
+	if h.StreamRequestBudget > 0 {
+		return h.StreamRequestBudget
+	}
+	return defaultStreamRequestBudget
+}
+
+type streamRecoveryResponse struct {
+	Channel   channelResponse `json:"channel"`
+	SessionID string          `json:"sessionId"`
+	JobIDs    []string        `json:"jobIds"`
+	// IngestError is set when tearing down the abandoned ingest failed; the
+	// channel is offline regardless.
+	IngestError string `json:"ingestError,omitempty"`
+}
+
+// recoverStream serves POST /api/channels/{id}/stream/recover. It resets a
+// channel whose start has outlived the starting timeout and records the
+// recovery in the audit log.
+func (h *Handler) recoverStream(actor models.User, channel models.Channel, w http.ResponseWriter, r *http.Request) {
+	recovery, err := h.Store.RecoverStream(channel.ID)
+	if err != nil {
+		switch {
+		case errors.Is(err, storage.ErrStreamNotStuck):
+			WriteRequestError(w, RequestError{Status: http.StatusConflict, CodeVal: "stream_not_stuck", Message: "the channel is not stuck starting", Err: err})
+		case errors.Is(err, storage.ErrIngestControllerUnavailable):
+			WriteRequestError(w, ServiceUnavailableError("ingest controller unavailable"))
+		default:
+			writeStorageError(w, http.StatusBadRequest, err)
+		}
+		return
+	}
+	h.invalidateChannelCache(r.Context(), channel.ID)
+	resp := streamRecoveryResponse{
+		Channel:   newChannelResponse(recovery.Channel),
+		SessionID: recovery.SessionID,
+		JobIDs:    append([]string{}, recovery.JobIDs...),
+	}
+	fields := []any{"action", "stream.recover", "user_id", actor.ID, "channel_id", channel.ID, "session_id", recovery.SessionID, "job_ids", resp.JobIDs}
+	if recovery.ShutdownErr != nil {
+		resp.IngestError = recovery.ShutdownErr.Error()
+		fields = append(fields, "ingest_error", resp.IngestError)
+	}
+	h.auditLogger().Info("audit", fields...)
+	WriteJSON(w, http.StatusOK, resp)
+}
+
+// checkRequestedLadder validates the renditions a creator asked for against
+// the platform transcode caps with the channel's override applied. Bitrates
+// come from the configured ladder profiles; names the profiles do not know
+// count towards the rendition and resolution caps only. Publishes that arrive
+// through SRS are not checked here because the transcoder clamps the ladder
+// it builds to the same caps.
+func (h *Handler) checkRequestedLadder(channel models.Channel, renditions []string) *ingest.TranscodeLimitError {
+	limits := h.TranscodeLimits.WithOverride(channel.TranscodeLimits)
+	if len(renditions) == 0 || limits.IsZero() {
+		return nil
+	}
+	bitrates := make(map[string]int, len(h.LadderProfiles))
+	for _, profile := range h.LadderProfiles {
+		bitrates[strings.ToLower(strings.TrimSpace(profile.Name))] = profile.Bitrate
+	}
+	ladder := make([]ingest.Rendition, 0, len(renditions))
+	for _, name := range renditions {
+		ladder = append(ladder, ingest.Rendition{Name: name, Bitrate: bitrates[strings.ToLower(strings.TrimSpace(name))]})
+	}
+	var limitErr *ingest.TranscodeLimitError
+	if err := ingest.CheckLadder(ladder, limits); errors.As(err, &limitErr) {
+		return limitErr
+	}
+	return nil
+}
+
+// transcodeLimitRequestError maps a cap violation to a 422 whose code names
+// the cap that was broken and whose message carries the offending values.
+func transcodeLimitRequestError(err *ingest.TranscodeLimitError) RequestError {
+	return RequestError{Status: http.StatusUnprocessableEntity, CodeVal: err.Reason, Message: err.Error(), Err: err}
+}
+
+// handleChannelSessions serves /channels/{id}/sessions. Listing sessions is
+// limited to channel managers; a single session is public, with ingest
+// details kept for the owner, moderators, and admins.
+func (h *Handler) handleChannelSessions(channel models.Channel, remaining []string, w http.ResponseWriter, r *http.Request) {
+	if len(remaining) > 1 {
+		WriteError(w, http.StatusNotFound, fmt.Errorf("unknown channel path"))
+		return
+	}
+	if len(remaining) == 0 || remaining[0] == "" {
+		if _, ok := h.ensureChannelAccess(w, r, channel); !ok {
+			return
+		}
+		if r.Method != http.MethodGet {
+			WriteMethodNotAllowed(w, r, http.MethodGet)
+			return
+		}
+		sessions, err := h.Store.ListStreamSessions(channel.ID)
+		if err != nil {
+			writeStorageError(w, http.StatusBadRequest, err)
+			return
+		}
+		response := make([]sessionResponse, 0, len(sessions))
+		for _, session := range sessions {
+			response = append(response, newSessionResponse(session))
+		}
+		WriteJSON(w, http.StatusOK, response)
+		return
+	}
+
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	sessions, err := h.Store.ListStreamSessions(channel.ID)
+	if err != nil {
+		writeStorageError(w, http.StatusBadRequest, err)
+		return
+	}
+	sessionID := remaining[0]
+	for _, session := range sessions {
+		if session.ID != sessionID {
+			continue
+		}
+		response := newSessionResponse(session)
+		var viewer *models.User
+		if actor, ok := UserFromContext(r.Context()); ok {
+			viewer = &actor
+		}
+		if viewer == nil || !h.canModerateChannel(*viewer, channel) {
+			response.OriginURL = ""
+			response.PublishSource = ""
+			response.IngestEndpoints = nil
+			response.IngestJobIDs = nil
+			// Restricted and mature channels hand out playback URLs through the
+			// playback endpoint, which applies their gates.
+			gated := channel.PlaybackRestriction != "" && (viewer == nil || !bypassesPlaybackGates(*viewer, channel))
+			if gated || ageGate(channel, viewer, h.now()) != "" {
+				response.PlaybackURL = ""
+				response.RenditionManifests = nil
+			}
+		}
+		WriteJSON(w, http.StatusOK, response)
+		return
+	}
+	WriteError(w, http.StatusNotFound, fmt.Errorf("session %s not found", sessionID))
+}
+
 // SRSHook processes callbacks from SRS http_hooks to validate publish/play
 // events and update stream session state accordingly.
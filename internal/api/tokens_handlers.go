@@ -0,0 +1,119 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+type createAPITokenRequest struct {
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	ExpiresAt *string  `json:"expiresAt"`
+}
+
+type apiTokenResponse struct {
+	ID        string   `json:"id"`
+	Name      string   `json:"name"`
+	Scopes    []string `json:"scopes"`
+	CreatedAt string   `json:"createdAt"`
+	ExpiresAt *string  `json:"expiresAt,omitempty"`
+}
+
+type createAPITokenResponse struct {
+	apiTokenResponse
+	Token string `json:"token"`
+}
+
+func newAPITokenResponse(token models.APIToken) apiTokenResponse {
+	response := apiTokenResponse{
+		ID:        token.ID,
+		Name:      token.Name,
+		Scopes:    append([]string{}, token.Scopes...),
+		CreatedAt: token.CreatedAt.Format(time.RFC3339Nano),
+	}
+	if token.ExpiresAt != nil {
+		expires := token.ExpiresAt.Format(time.RFC3339Nano)
+		response.ExpiresAt = &expires
+	}
+	return response
+}
+
+// userAPITokens serves /api/users/me/tokens and /api/users/me/tokens/{id}.
+// Tokens can only be managed from a browser session so a leaked token cannot
+// mint or revoke others.
+func (h *Handler) userAPITokens(w http.ResponseWriter, r *http.Request, tokenID string) {
+	user, ok := h.requireAuthenticatedUser(w, r)
+	if !ok {
+		return
+	}
+	if _, viaToken := APITokenFromContext(r.Context()); viaToken {
+		WriteError(w, http.StatusForbidden, fmt.Errorf("access tokens cannot manage access tokens"))
+		return
+	}
+
+	if tokenID != "" {
+		if r.Method != http.MethodDelete {
+			WriteMethodNotAllowed(w, r, http.MethodDelete)
+			return
+		}
+		if err := h.Store.RevokeAPIToken(user.ID, tokenID); err != nil {
+			if errors.Is(err, storage.ErrAPITokenNotFound) {
+				writeStorageError(w, http.StatusNotFound, err)
+				return
+			}
+			writeStorageError(w, http.StatusInternalServerError, err)
+			return
+		}
+		w.WriteHeader(http.StatusNoContent)
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		tokens, err := h.Store.ListAPITokens(user.ID)
+		if err != nil {
+			writeStorageError(w, http.StatusInternalServerError, err)
+			return
+		}
+		response := make([]apiTokenResponse, 0, len(tokens))
+		for _, token := range tokens {
+			response = append(response, newAPITokenResponse(token))
+		}
+		WriteJSON(w, http.StatusOK, response)
+	case http.MethodPost:
+		var req createAPITokenRequest
+		if !DecodeAndValidate(w, r, &req) {
+			return
+		}
+		params := storage.CreateAPITokenParams{
+			UserID: user.ID,
+			Name:   req.Name,
+			Scopes: req.Scopes,
+		}
+		if req.ExpiresAt != nil && strings.TrimSpace(*req.ExpiresAt) != "" {
+			expiresAt, err := time.Parse(time.RFC3339, strings.TrimSpace(*req.ExpiresAt))
+			if err != nil {
+				WriteRequestError(w, ValidationError("expiresAt must be an RFC3339 timestamp"))
+				return
+			}
+			params.ExpiresAt = &expiresAt
+		}
+		token, secret, err := h.Store.CreateAPIToken(params)
+		if err != nil {
+			writeStorageError(w, http.StatusBadRequest, err)
+			return
+		}
+		WriteJSON(w, http.StatusCreated, createAPITokenResponse{
+			apiTokenResponse: newAPITokenResponse(token),
+			Token:            secret,
+		})
+	default:
+		WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodPost)
+	}
+}
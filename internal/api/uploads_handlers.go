@@ -2,6 +2,7 @@ package api
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
 	"crypto/subtle"
 	"encoding/hex"
 	"errors"
@@ -28,20 +29,33 @@ var (
 )
 
 type uploadResponse struct {
-	ID          string            `json:"id"`
-	ChannelID   string            `json:"channelId"`
-	Title       string            `json:"title"`
-	Filename    string            `json:"filename"`
-	SizeBytes   int64             `json:"sizeBytes"`
-	Status      string            `json:"status"`
-	Progress    int               `json:"progress"`
-	RecordingID *string           `json:"recordingId,omitempty"`
-	PlaybackURL string            `json:"playbackUrl,omitempty"`
-	Metadata    map[string]string `json:"metadata,omitempty"`
-	Error       string            `json:"error,omitempty"`
-	CreatedAt   string            `json:"createdAt"`
-	UpdatedAt   string            `json:"updatedAt"`
-	CompletedAt *string           `json:"completedAt,omitempty"`
+	ID          string               `json:"id"`
+	ChannelID   string               `json:"channelId"`
+	Title       string               `json:"title"`
+	Filename    string               `json:"filename"`
+	SizeBytes   int64                `json:"sizeBytes"`
+	OutputBytes int64                `json:"outputBytes,omitempty"`
+	ContentHash string               `json:"contentHash,omitempty"`
+	Status      string               `json:"status"`
+	Progress    int                  `json:"progress"`
+	RecordingID *string              `json:"recordingId,omitempty"`
+	PlaybackURL string               `json:"playbackUrl,omitempty"`
+	Metadata    map[string]string    `json:"metadata,omitempty"`
+	Error       string               `json:"error,omitempty"`
+	CreatedAt   string               `json:"createdAt"`
+	UpdatedAt   string               `json:"updatedAt"`
+	CompletedAt *string              `json:"completedAt,omitempty"`
+	Steps       []uploadStepResponse `json:"steps,omitempty"`
+	// FailedStep names the step processing stopped at, for failed uploads.
+	FailedStep string `json:"failedStep,omitempty"`
+}
+
+type uploadStepResponse struct {
+	Name       string  `json:"name"`
+	Status     string  `json:"status"`
+	StartedAt  *string `json:"startedAt,omitempty"`
+	FinishedAt *string `json:"finishedAt,omitempty"`
+	Detail     string  `json:"detail,omitempty"`
 }
 
 type uploadedMedia struct {
@@ -49,6 +63,9 @@ type uploadedMedia struct {
 	size         int64
 	originalName string
 	contentType  string
+	// contentHash is the hex-encoded SHA-256 computed while the file was
+	// received.
+	contentHash string
 }
 
 type createUploadRequest struct {
@@ -56,23 +73,40 @@ type createUploadRequest struct {
 	Title       string            `json:"title"`
 	Filename    string            `json:"filename"`
 	SizeBytes   int64             `json:"sizeBytes"`
+	ContentHash string            `json:"contentHash"`
 	PlaybackURL string            `json:"playbackUrl"`
 	Metadata    map[string]string `json:"metadata"`
 }
 
+// transcodedUploadMetadata lists the metadata the upload processor derives
+// from a transcode, which a deduplicated upload inherits from the original.
+var transcodedUploadMetadata = []string{
+	"transcodeJobId",
+	"renditions",
+	"playbackUrl",
+	"durationSeconds",
+	"resolution",
+	"videoCodec",
+	"audioCodec",
+	"skippedRenditions",
+	"thumbnailUrl",
+}
+
 func newUploadResponse(upload models.Upload) uploadResponse {
 	resp := uploadResponse{
-		ID:        upload.ID,
-		ChannelID: upload.ChannelID,
-		Title:     upload.Title,
-		Filename:  upload.Filename,
-		SizeBytes: upload.SizeBytes,
-		Status:    upload.Status,
-		Progress:  upload.Progress,
-		Metadata:  nil,
-		Error:     upload.Error,
-		CreatedAt: upload.CreatedAt.Format(time.RFC3339Nano),
-		UpdatedAt: upload.UpdatedAt.Format(time.RFC3339Nano),
+		ID:          upload.ID,
+		ChannelID:   upload.ChannelID,
+		Title:       upload.Title,
+		Filename:    upload.Filename,
+		SizeBytes:   upload.SizeBytes,
+		OutputBytes: upload.OutputBytes,
+		ContentHash: upload.ContentHash,
+		Status:      upload.Status,
+		Progress:    upload.Progress,
+		Metadata:    nil,
+		Error:       upload.Error,
+		CreatedAt:   upload.CreatedAt.Format(time.RFC3339Nano),
+		UpdatedAt:   upload.UpdatedAt.Format(time.RFC3339Nano),
 	}
 	if upload.Metadata != nil {
 		meta := make(map[string]string, len(upload.Metadata))
@@ -95,6 +129,24 @@ func newUploadResponse(upload models.Upload) uploadResponse {
 	if strings.TrimSpace(resp.Error) == "" {
 		resp.Error = ""
 	}
+	if len(upload.Steps) > 0 {
+		resp.Steps = make([]uploadStepResponse, 0, len(upload.Steps))
+		for _, step := range upload.Steps {
+			item := uploadStepResponse{Name: step.Name, Status: step.Status, Detail: step.Detail}
+			if step.StartedAt != nil {
+				started := step.StartedAt.Format(time.RFC3339Nano)
+				item.StartedAt = &started
+			}
+			if step.FinishedAt != nil {
+				finished := step.FinishedAt.Format(time.RFC3339Nano)
+				item.FinishedAt = &finished
+			}
+			resp.Steps = append(resp.Steps, item)
+		}
+	}
+	if failed, ok := upload.FailedStep(); ok {
+		resp.FailedStep = failed.Name
+	}
 	return resp
 }
 
@@ -126,13 +178,13 @@ func (h *Handler) Uploads(w http.ResponseWriter, r *http.Request) {
 			WriteError(w, http.StatusNotFound, fmt.Errorf("channel %s not found", channelID))
 			return
 		}
-		if channel.OwnerID != actor.ID && !actor.HasRole(roleAdmin) {
+		if !h.canManageChannelMedia(actor, channel) {
 			WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
 			return
 		}
 		uploads, err := h.Store.ListUploads(channelID)
 		if err != nil {
-			WriteError(w, http.StatusBadRequest, err)
+			writeStorageError(w, http.StatusBadRequest, err)
 			return
 		}
 		response := make([]uploadResponse, 0, len(uploads))
@@ -145,6 +197,9 @@ func (h *Handler) Uploads(w http.ResponseWriter, r *http.Request) {
 		if !ok {
 			return
 		}
+		if !h.requireScope(w, r, storage.APITokenScopeManageChannel) {
+			return
+		}
 		contentType := strings.ToLower(strings.TrimSpace(r.Header.Get("Content-Type")))
 		if strings.HasPrefix(contentType, "multipart/form-data") {
 			h.createUploadFromMultipart(w, r, actor)
@@ -186,7 +241,7 @@ func (h *Handler) UploadByID(w http.ResponseWriter, r *http.Request) {
 			WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
 			return
 		}
-		if channel.OwnerID != actor.ID && !actor.HasRole(roleAdmin) {
+		if !h.canManageChannelMedia(actor, channel) {
 			WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
 			return
 		}
@@ -196,12 +251,15 @@ func (h *Handler) UploadByID(w http.ResponseWriter, r *http.Request) {
 			WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
 			return
 		}
-		if channel.OwnerID != actor.ID && !actor.HasRole(roleAdmin) {
+		if !h.canManageChannelMedia(actor, channel) {
 			WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
 			return
 		}
+		if !h.requireScope(w, r, storage.APITokenScopeManageChannel) {
+			return
+		}
 		if err := h.Store.DeleteUpload(uploadID); err != nil {
-			WriteError(w, http.StatusBadRequest, err)
+			writeStorageError(w, http.StatusBadRequest, err)
 			return
 		}
 		h.deleteUploadMedia(upload)
@@ -276,6 +334,8 @@ func (h *Handler) createUploadFromMultipart(w http.ResponseWriter, r *http.Reque
 			req.Filename = value
 		case "playbackUrl":
 			req.PlaybackURL = value
+		case "contentHash":
+			req.ContentHash = value
 		case "sizeBytes":
 			if value != "" {
 				if size, parseErr := strconv.ParseInt(value, 10, 64); parseErr == nil {
@@ -318,6 +378,14 @@ func (h *Handler) createUploadFromMultipart(w http.ResponseWriter, r *http.Reque
 }
 
 func (h *Handler) createUploadEntry(r *http.Request, actor models.User, req createUploadRequest, media *uploadedMedia) (models.Upload, int, error) {
+	if media != nil {
+		// persistUploadMedia clears tempPath once the file has been kept.
+		defer func() {
+			if media.tempPath != "" {
+				_ = os.Remove(media.tempPath)
+			}
+		}()
+	}
 	channelID := strings.TrimSpace(req.ChannelID)
 	if channelID == "" {
 		return models.Upload{}, http.StatusBadRequest, fmt.Errorf("channelId is required")
@@ -326,9 +394,24 @@ func (h *Handler) createUploadEntry(r *http.Request, actor models.User, req crea
 	if !exists {
 		return models.Upload{}, http.StatusNotFound, fmt.Errorf("channel %s not found", channelID)
 	}
-	if channel.OwnerID != actor.ID && !actor.HasRole(roleAdmin) {
+	if !h.canManageChannelMedia(actor, channel) {
 		return models.Upload{}, http.StatusForbidden, fmt.Errorf("forbidden")
 	}
+	contentHash := strings.ToLower(strings.TrimSpace(req.ContentHash))
+	if contentHash != "" && !validContentHash(contentHash) {
+		return models.Upload{}, http.StatusBadRequest, fmt.Errorf("contentHash must be a hex-encoded SHA-256")
+	}
+	// A hash is trusted for deduplication only once the server has computed
+	// it. Hashes supplied with a source URL are checked by the transcoder
+	// when it fetches the source instead.
+	verified := false
+	if media != nil && media.contentHash != "" {
+		if contentHash != "" && contentHash != media.contentHash {
+			return models.Upload{}, http.StatusBadRequest, fmt.Errorf("contentHash does not match the uploaded file")
+		}
+		contentHash = media.contentHash
+		verified = true
+	}
 	metadata := cloneStringMap(req.Metadata)
 	playbackURL := strings.TrimSpace(req.PlaybackURL)
 	if playbackURL != "" {
@@ -346,13 +429,31 @@ func (h *Handler) createUploadEntry(r *http.Request, actor models.User, req crea
 		Title:       req.Title,
 		Filename:    req.Filename,
 		SizeBytes:   sizeBytes,
+		ContentHash: contentHash,
 		Metadata:    metadata,
 		PlaybackURL: playbackURL,
 	}
+	var (
+		original  models.Upload
+		duplicate bool
+	)
+	if verified {
+		original, duplicate = h.Store.FindReadyUploadByContentHash(channelID, contentHash)
+	}
 	upload, err := h.Store.CreateUpload(params)
 	if err != nil {
+		if quotaErr, ok := storageQuotaError(err); ok {
+			return models.Upload{}, quotaErr.StatusCode(), quotaErr
+		}
 		return models.Upload{}, http.StatusBadRequest, err
 	}
+	if duplicate {
+		completed, dedupErr := h.completeDuplicateUpload(upload, original)
+		if dedupErr != nil {
+			return models.Upload{}, http.StatusInternalServerError, dedupErr
+		}
+		return completed, 0, nil
+	}
 	if media != nil {
 		updated, attachErr := h.attachMediaToUpload(r, upload, metadata, media)
 		if attachErr != nil {
@@ -366,6 +467,56 @@ func (h *Handler) createUploadEntry(r *http.Request, actor models.User, req crea
 	return upload, 0, nil
 }
 
+// completeDuplicateUpload marks a new upload ready using the output of an
+// earlier upload of the same file on the same channel, skipping the
+// transcode. The metadata records which upload it was deduplicated from.
+func (h *Handler) completeDuplicateUpload(upload, original models.Upload) (models.Upload, error) {
+	metadata := make(map[string]string, len(transcodedUploadMetadata)+1)
+	for _, key := range transcodedUploadMetadata {
+		if value := original.Metadata[key]; value != "" {
+			metadata[key] = value
+		}
+	}
+	metadata["dedupedFrom"] = original.ID
+	status := "ready"
+	progress := 100
+	playbackURL := original.PlaybackURL
+	recordingID := ""
+	if original.RecordingID != nil {
+		recordingID = *original.RecordingID
+	}
+	completedAt := time.Now().UTC()
+	steps := models.UploadSteps()
+	for i := range steps {
+		steps[i].Status = models.UploadStepDone
+		steps[i].FinishedAt = &completedAt
+	}
+	steps[0].Detail = fmt.Sprintf("reused the output of upload %s", original.ID)
+	updated, err := h.Store.UpdateUpload(upload.ID, storage.UploadUpdate{
+		Steps:       steps,
+		Status:      &status,
+		Progress:    &progress,
+		RecordingID: &recordingID,
+		PlaybackURL: &playbackURL,
+		Metadata:    metadata,
+		CompletedAt: &completedAt,
+	})
+	if err != nil {
+		_ = h.Store.DeleteUpload(upload.ID)
+		return models.Upload{}, fmt.Errorf("complete duplicate upload: %w", err)
+	}
+	return updated, nil
+}
+
+// validContentHash reports whether value is a hex-encoded SHA-256.
+func validContentHash(value string) bool {
+	if len(value) != sha256.Size*2 {
+		return false
+	}
+	_, err := hex.DecodeString(value)
+	return err == nil
+}
+
 func (h *Handler) saveMultipartFile(part *multipart.Part) (*uploadedMedia, error) {
 	defer func() {
 		_ = part.Close()
@@ -378,7 +529,8 @@ func (h *Handler) saveMultipartFile(part *multipart.Part) (*uploadedMedia, error
 	defer func() {
 		_ = tmp.Close()
 	}()
-	written, err := io.Copy(tmp, part)
+	hasher := sha256.New()
+	written, err := io.Copy(io.MultiWriter(tmp, hasher), part)
 	if err != nil {
 		_ = os.Remove(tmp.Name())
 		return nil, fmt.Errorf("save upload: %w", err)
@@ -388,6 +540,7 @@ func (h *Handler) saveMultipartFile(part *multipart.Part) (*uploadedMedia, error
 		size:         written,
 		originalName: part.FileName(),
 		contentType:  part.Header.Get("Content-Type"),
+		contentHash:  hex.EncodeToString(hasher.Sum(nil)),
 	}, nil
 }
 
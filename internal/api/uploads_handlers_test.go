@@ -2,17 +2,23 @@ package api
 
 import (
 	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
 	"errors"
 	"io"
 	"log/slog"
+	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
 	"os"
 	"path/filepath"
 	"strings"
 	"testing"
+	"time"
 
 	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
 )
 
 func TestServeUploadMediaLogsOpenError(t *testing.T) {
@@ -154,3 +160,244 @@ func TestServeUploadMediaLogsStatError(t *testing.T) {
 		t.Fatalf("log missing wrapped error: %s", logOutput)
 	}
 }
+
+func postUploadFile(t *testing.T, h *Handler, actor models.User, channelID string, content []byte, fields map[string]string) *httptest.ResponseRecorder {
+	t.Helper()
+	var body bytes.Buffer
+	form := multipart.NewWriter(&body)
+	if err := form.WriteField("channelId", channelID); err != nil {
+		t.Fatalf("WriteField: %v", err)
+	}
+	for name, value := range fields {
+		if err := form.WriteField(name, value); err != nil {
+			t.Fatalf("WriteField: %v", err)
+		}
+	}
+	part, err := form.CreateFormFile("file", "vod.mp4")
+	if err != nil {
+		t.Fatalf("CreateFormFile: %v", err)
+	}
+	if _, err := part.Write(content); err != nil {
+		t.Fatalf("write file: %v", err)
+	}
+	if err := form.Close(); err != nil {
+		t.Fatalf("close form: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/uploads", &body)
+	req.Header.Set("Content-Type", form.FormDataContentType())
+	rec := httptest.NewRecorder()
+	h.Uploads(rec, withUser(req, actor))
+	return rec
+}
+
+// seedReadyUpload stores a transcoded upload of content on the channel.
+func seedReadyUpload(t *testing.T, store *storage.Storage, channelID string, content []byte) models.Upload {
+	t.Helper()
+	sum := sha256.Sum256(content)
+	upload, err := store.CreateUpload(storage.CreateUploadParams{ChannelID: channelID, Filename: "vod.mp4", ContentHash: hex.EncodeToString(sum[:])})
+	if err != nil {
+		t.Fatalf("CreateUpload: %v", err)
+	}
+	status, progress := "ready", 100
+	recordingID, playbackURL := "recording-1", "https://vod.example.com/"+upload.ID+"/index.m3u8"
+	upload, err = store.UpdateUpload(upload.ID, storage.UploadUpdate{
+		Status:      &status,
+		Progress:    &progress,
+		RecordingID: &recordingID,
+		PlaybackURL: &playbackURL,
+		Metadata:    map[string]string{"transcodeJobId": "job-1", "renditions": "720p,480p", "mediaToken": "secret"},
+	})
+	if err != nil {
+		t.Fatalf("UpdateUpload: %v", err)
+	}
+	return upload
+}
+
+func TestCreateUploadDedupesWithinChannel(t *testing.T) {
+	h, store := newTestHandler(t)
+	h.UploadMediaDir = t.TempDir()
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Creator", Email: "creator@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Main", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	content := []byte("the same video, uploaded twice")
+	original := seedReadyUpload(t, store, channel.ID, content)
+
+	rec := postUploadFile(t, h, owner, channel.ID, content, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp uploadResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.ID == original.ID || resp.Status != "ready" || resp.Progress != 100 || resp.CompletedAt == nil {
+		t.Fatalf("expected a new upload completed without transcoding, got %+v", resp)
+	}
+	if resp.ContentHash != original.ContentHash {
+		t.Fatalf("expected content hash %q, got %q", original.ContentHash, resp.ContentHash)
+	}
+	if resp.PlaybackURL != original.PlaybackURL || resp.RecordingID == nil || *resp.RecordingID != "recording-1" {
+		t.Fatalf("expected the original playback and recording to be reused, got %+v", resp)
+	}
+	if resp.Metadata["dedupedFrom"] != original.ID || resp.Metadata["transcodeJobId"] != "job-1" || resp.Metadata["renditions"] != "720p,480p" {
+		t.Fatalf("expected metadata noting the dedup, got %+v", resp.Metadata)
+	}
+	if _, leaked := resp.Metadata["mediaToken"]; leaked {
+		t.Fatal("expected the original's media token not to be copied")
+	}
+	if entries, err := os.ReadDir(h.UploadMediaDir); err != nil || len(entries) != 0 {
+		t.Fatalf("expected no media kept for a duplicate upload, got %v (err %v)", entries, err)
+	}
+}
+
+func TestCreateUploadNeverDedupesAcrossChannels(t *testing.T) {
+	h, store := newTestHandler(t)
+	h.UploadMediaDir = t.TempDir()
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Creator", Email: "creator@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	first, err := store.CreateChannel(owner.ID, "First", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	second, err := store.CreateChannel(owner.ID, "Second", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	content := []byte("a video shared between channels")
+	seedReadyUpload(t, store, first.ID, content)
+
+	rec := postUploadFile(t, h, owner, second.ID, content, nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp uploadResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Status != "pending" || resp.Metadata["dedupedFrom"] != "" || resp.PlaybackURL != "" {
+		t.Fatalf("expected an independent pending upload, got %+v", resp)
+	}
+	if resp.Metadata["mediaPath"] == "" {
+		t.Fatalf("expected the media to be stored for transcoding, got %+v", resp.Metadata)
+	}
+}
+
+func TestCreateUploadRejectsMismatchedContentHash(t *testing.T) {
+	h, store := newTestHandler(t)
+	h.UploadMediaDir = t.TempDir()
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Creator", Email: "creator@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Main", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+
+	for name, hash := range map[string]string{"malformed": "abc", "mismatched": strings.Repeat("0", 64)} {
+		rec := postUploadFile(t, h, owner, channel.ID, []byte("video"), map[string]string{"contentHash": hash})
+		if rec.Code != http.StatusBadRequest {
+			t.Fatalf("%s: expected 400, got %d: %s", name, rec.Code, rec.Body.String())
+		}
+	}
+	if uploads, err := store.ListUploads(channel.ID); err != nil || len(uploads) != 0 {
+		t.Fatalf("expected no uploads created, got %d (err %v)", len(uploads), err)
+	}
+	if entries, err := os.ReadDir(h.UploadMediaDir); err != nil || len(entries) != 0 {
+		t.Fatalf("expected rejected media to be discarded, got %v (err %v)", entries, err)
+	}
+}
+
+func TestUploadWritesRequireManageChannelScope(t *testing.T) {
+	h, store := newTestHandler(t)
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Creator", Email: "creator@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Main", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	upload, err := store.CreateUpload(storage.CreateUploadParams{ChannelID: channel.ID, Title: "Existing"})
+	if err != nil {
+		t.Fatalf("CreateUpload: %v", err)
+	}
+	readOnly := models.APIToken{ID: "reader", Scopes: []string{storage.APITokenScopeRead}}
+
+	req := httptest.NewRequest(http.MethodPost, "/api/uploads", strings.NewReader(`{"channelId":"`+channel.ID+`","title":"New"}`))
+	req = withUser(req, owner)
+	req = req.WithContext(ContextWithAPIToken(req.Context(), readOnly))
+	rec := httptest.NewRecorder()
+	h.Uploads(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected a read-only token to be refused, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	req = httptest.NewRequest(http.MethodDelete, "/api/uploads/"+upload.ID, nil)
+	req = withUser(req, owner)
+	req = req.WithContext(ContextWithAPIToken(req.Context(), readOnly))
+	rec = httptest.NewRecorder()
+	h.UploadByID(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected a read-only token to be refused, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if _, ok := store.GetUpload(upload.ID); !ok {
+		t.Fatal("expected the upload to be kept")
+	}
+}
+
+func TestGetUploadReturnsPipelineSteps(t *testing.T) {
+	h, store := newTestHandler(t)
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Creator", Email: "creator@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Main", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	upload, err := store.CreateUpload(storage.CreateUploadParams{ChannelID: channel.ID, Filename: "vod.mp4"})
+	if err != nil {
+		t.Fatalf("CreateUpload: %v", err)
+	}
+	started := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	finished := started.Add(5 * time.Second)
+	if err := store.UpdateUploadStep(upload.ID, models.UploadStep{Name: models.UploadStepProbe, Status: models.UploadStepDone, StartedAt: &started, FinishedAt: &finished, Detail: "1920x1080"}); err != nil {
+		t.Fatalf("UpdateUploadStep: %v", err)
+	}
+	if err := store.UpdateUploadStep(upload.ID, models.UploadStep{Name: models.UploadStepTranscode, Status: models.UploadStepFailed, StartedAt: &finished, FinishedAt: &finished, Detail: "transcoder out of storage"}); err != nil {
+		t.Fatalf("UpdateUploadStep: %v", err)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/uploads/"+upload.ID, nil)
+	rec := httptest.NewRecorder()
+	h.UploadByID(rec, withUser(req, owner))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var resp uploadResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+		t.Fatalf("decode response: %v", err)
+	}
+	if resp.Progress != 10 || resp.FailedStep != models.UploadStepTranscode || len(resp.Steps) != 5 {
+		t.Fatalf("expected the failed transcode highlighted at 10%%, got %+v", resp)
+	}
+	probe := resp.Steps[0]
+	if probe.Name != models.UploadStepProbe || probe.Status != models.UploadStepDone || probe.Detail != "1920x1080" ||
+		probe.StartedAt == nil || *probe.StartedAt != "2026-03-01T12:00:00Z" || probe.FinishedAt == nil || *probe.FinishedAt != "2026-03-01T12:00:05Z" {
+		t.Fatalf("expected the probe step in the payload, got %+v", probe)
+	}
+	if resp.Steps[1].Detail != "transcoder out of storage" {
+		t.Fatalf("expected the failure detail, got %+v", resp.Steps[1])
+	}
+	if pending := resp.Steps[4]; pending.Name != models.UploadStepFinalize || pending.Status != models.UploadStepPending || pending.StartedAt != nil {
+		t.Fatalf("expected finalize still pending, got %+v", pending)
+	}
+}
@@ -5,12 +5,16 @@ import (
 	"errors"
 	"fmt"
 	"log/slog"
+	"net/http"
+	"strconv"
 	"strings"
 	"sync"
 	"time"
 
 	"bitriver-live/internal/ingest"
+	"bitriver-live/internal/malware"
 	"bitriver-live/internal/models"
+	"bitriver-live/internal/observability/health"
 	"bitriver-live/internal/storage"
 )
 
@@ -21,6 +25,7 @@ type UploadStore interface {
 	ListPendingUploads(ctx context.Context, limit int) ([]models.Upload, error)
 	GetUpload(ctx context.Context, id string) (models.Upload, bool)
 	UpdateUpload(ctx context.Context, id string, update storage.UploadUpdate) (models.Upload, error)
+	UpdateUploadStep(ctx context.Context, id string, step models.UploadStep) error
 }
 
 // UploadIngestClient captures the ingest functionality needed to process
@@ -59,7 +64,11 @@ func (s repositoryUploadStore) ListPendingUploads(ctx context.Context, limit int
 		firstErr error
 	)
 
-	for _, channel := range s.repo.ListChannels("", "") {
+	channels, err := s.repo.ListChannels("", "")
+	if err != nil {
+		return nil, err
+	}
+	for _, channel := range channels {
 		if limit > 0 && len(pending) >= limit {
 			break
 		}
@@ -117,6 +126,19 @@ func (s repositoryUploadStore) UpdateUpload(ctx context.Context, id string, upda
 	return s.repo.UpdateUpload(id, update)
 }
 
+func (s repositoryUploadStore) UpdateUploadStep(ctx context.Context, id string, step models.UploadStep) error {
+	if s.repo == nil {
+		return fmt.Errorf("upload store unavailable")
+	}
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	return s.repo.UpdateUploadStep(id, step)
+}
+
 // UploadProcessorConfig describes the collaborators and tunable settings used
 // to process archived uploads, including storage, ingest coordination, worker
 // concurrency, and back pressure limits.
@@ -128,6 +150,36 @@ type UploadProcessorConfig struct {
 	QueueSize  int
 	Timeout    time.Duration
 	Logger     *slog.Logger
+	// Health registers the processor as the "uploads" background component.
+	// Nil disables heartbeat reporting.
+	Health *health.Registry
+	// OutputPollInterval is how often the processor asks the transcoder
+	// whether a ready upload has finished writing its output, so its size
+	// can count towards the channel's storage. Only used when Ingest
+	// implements ingest.UploadOutputReporter.
+	OutputPollInterval time.Duration
+	// Scanner checks each uploaded file for malware before it is handed
+	// to the transcoder. Nil or malware.NoopScanner skips scanning.
+	Scanner malware.Scanner
+	// ScanTimeout bounds one scan. Zero uses two minutes.
+	ScanTimeout time.Duration
+	// ScanFailOpen processes an upload unscanned when the scan errors or
+	// times out, or the source of an upload imported from a URL cannot be
+	// fetched. By default those uploads fail.
+	ScanFailOpen bool
+	// SourceClient fetches uploads imported from a URL for the scanner.
+	// Nil uses a client without its own timeout; ScanTimeout bounds each
+	// fetch.
+	SourceClient *http.Client
+	// Sources reads uploaded files for the scanner and quarantines the
+	// infected ones.
+	Sources UploadSources
+	// SecurityEvents records an event on the channel for each infected
+	// upload. Nil skips the event.
+	SecurityEvents UploadSecurityEvents
+	// AuditLogger receives an audit record for each quarantined upload.
+	// Nil uses Logger.
+	AuditLogger *slog.Logger
 }
 
 // UploadProcessor runs background workers that resolve pending uploads by
@@ -140,6 +192,16 @@ type UploadProcessor struct {
 	workers    int
 	timeout    time.Duration
 	logger     *slog.Logger
+	health     *health.Component
+	outputPoll time.Duration
+
+	scanner        malware.Scanner
+	scanTimeout    time.Duration
+	scanFailOpen   bool
+	sources        UploadSources
+	sourceClient   *http.Client
+	securityEvents UploadSecurityEvents
+	auditLogger    *slog.Logger
 
 	ctx    context.Context
 	cancel context.CancelFunc
@@ -153,10 +215,11 @@ type UploadProcessor struct {
 }
 
 const (
-	defaultUploadWorkers   = 2
-	defaultUploadQueueSize = 64
-	defaultUploadTimeout   = 30 * time.Minute
-	retryDelay             = 50 * time.Millisecond
+	defaultUploadWorkers    = 2
+	defaultUploadQueueSize  = 64
+	defaultUploadTimeout    = 30 * time.Minute
+	defaultUploadOutputPoll = 10 * time.Second
+	retryDelay              = 50 * time.Millisecond
 )
 
 // NewUploadProcessor configures a worker pool for upload processing, applying
@@ -179,6 +242,22 @@ func NewUploadProcessor(cfg UploadProcessorConfig) *UploadProcessor {
 	if logger == nil {
 		logger = slog.Default()
 	}
+	outputPoll := cfg.OutputPollInterval
+	if outputPoll <= 0 {
+		outputPoll = defaultUploadOutputPoll
+	}
+	scanTimeout := cfg.ScanTimeout
+	if scanTimeout <= 0 {
+		scanTimeout = defaultUploadScanTimeout
+	}
+	auditLogger := cfg.AuditLogger
+	if auditLogger == nil {
+		auditLogger = logger
+	}
+	sourceClient := cfg.SourceClient
+	if sourceClient == nil {
+		sourceClient = &http.Client{}
+	}
 	ctx, cancel := context.WithCancel(context.Background())
 	processor := &UploadProcessor{
 		store:      cfg.Store,
@@ -187,10 +266,22 @@ func NewUploadProcessor(cfg UploadProcessorConfig) *UploadProcessor {
 		workers:    workers,
 		timeout:    timeout,
 		logger:     logger,
+		outputPoll: outputPoll,
 		ctx:        ctx,
 		cancel:     cancel,
 		queue:      make(chan string, queueSize),
 		inFlight:   make(map[string]struct{}),
+
+		scanner:        cfg.Scanner,
+		scanTimeout:    scanTimeout,
+		scanFailOpen:   cfg.ScanFailOpen,
+		sources:        cfg.Sources,
+		sourceClient:   sourceClient,
+		securityEvents: cfg.SecurityEvents,
+		auditLogger:    auditLogger,
+		// Workers cannot heartbeat while an upload is transcoding, so the
+		// stall threshold allows for a full upload timeout.
+		health: cfg.Health.RegisterComponent("uploads", health.WithStaleAfter(timeout+health.DefaultStaleAfter)),
 	}
 	return processor
 }
@@ -251,10 +342,15 @@ func (p *UploadProcessor) Enqueue(id string) {
 
 func (p *UploadProcessor) worker() {
 	defer p.wg.Done()
+	heartbeat := time.NewTicker(p.health.HeartbeatInterval())
+	defer heartbeat.Stop()
+	p.health.Heartbeat()
 	for {
 		select {
 		case <-p.ctx.Done():
 			return
+		case <-heartbeat.C:
+			p.health.Heartbeat()
 		case id := <-p.queue:
 			if strings.TrimSpace(id) == "" {
 				continue
@@ -264,6 +360,7 @@ func (p *UploadProcessor) worker() {
 			}
 			p.processUpload(id)
 			p.finishWork(id)
+			p.health.Heartbeat()
 		}
 	}
 }
@@ -292,6 +389,7 @@ func (p *UploadProcessor) recoverPending() {
 	}
 	uploads, err := p.store.ListPendingUploads(p.ctx, 0)
 	if err != nil {
+		p.health.SetDegraded(fmt.Errorf("list pending uploads: %w", err))
 		p.logger.Error("failed to list pending uploads", "error", err)
 	}
 	for _, upload := range uploads {
@@ -316,6 +414,7 @@ func (p *UploadProcessor) processUpload(id string) {
 	if status == "ready" || status == "completed" || status == "failed" {
 		return
 	}
+	pipeline := p.newPipeline(id)
 	source := strings.TrimSpace(upload.Metadata["sourceUrl"])
 	if source == "" {
 		source = strings.TrimSpace(upload.Metadata["sourceURL"])
@@ -324,37 +423,56 @@ func (p *UploadProcessor) processUpload(id string) {
 		source = strings.TrimSpace(upload.PlaybackURL)
 	}
 	if source == "" {
-		p.failUpload(id, "", fmt.Errorf("source URL is required"))
+		p.failUpload(pipeline, models.UploadStepProbe, "", fmt.Errorf("source URL is required"))
 		return
 	}
 
 	processing := "processing"
-	progress := 10
 	metadata := map[string]string{"sourceUrl": source}
 	if _, err := p.store.UpdateUpload(p.ctx, id, storage.UploadUpdate{
 		Status:   &processing,
-		Progress: &progress,
 		Metadata: metadata,
 		Error:    stringPtr(""),
+		Steps:    pipeline.steps(),
 	}); err != nil {
+		p.health.SetDegraded(fmt.Errorf("mark upload %s processing: %w", id, err))
 		p.logger.Error("failed to mark upload processing", "upload_id", id, "error", err)
 		p.scheduleRetry(id)
 		return
 	}
+	p.health.SetHealthy()
 
+	// The transcoder probes and validates the source before it accepts the
+	// job, so the malware scan and the submission below are the probe step.
+	pipeline.start(models.UploadStepProbe, "")
 	if p.ingest == nil {
-		p.failUpload(id, source, fmt.Errorf("ingest controller unavailable"))
+		p.failUpload(pipeline, models.UploadStepProbe, source, fmt.Errorf("ingest controller unavailable"))
+		return
+	}
+	scan, err := p.scanUpload(upload, source)
+	if err != nil {
+		if p.ctx.Err() != nil {
+			// Shutting down mid-scan; the upload stays processing and is
+			// picked up again on restart.
+			return
+		}
+		p.failUpload(pipeline, models.UploadStepProbe, source, err)
 		return
 	}
 
+	// The transcoder fits the ladder to the source once it has probed it;
+	// when the source size is already known, skip oversized rungs up front.
+	renditions := ingest.SelectUploadLadder(p.renditions, uploadSourceMedia(upload.Metadata)).Renditions
+
 	ctx, cancel := context.WithTimeout(p.ctx, p.timeout)
 	defer cancel()
 	result, err := p.ingest.TranscodeUpload(ctx, ingest.UploadTranscodeParams{
-		ChannelID:  upload.ChannelID,
-		UploadID:   upload.ID,
-		SourceURL:  source,
-		Filename:   upload.Filename,
-		Renditions: ingest.CloneRenditions(p.renditions),
+		ChannelID:    upload.ChannelID,
+		UploadID:     upload.ID,
+		SourceURL:    source,
+		Filename:     upload.Filename,
+		ExpectedHash: upload.ContentHash,
+		Renditions:   renditions,
 	})
 	if err != nil {
 		if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
@@ -362,18 +480,38 @@ func (p *UploadProcessor) processUpload(id string) {
 				err = ctxErr
 			}
 		}
-		p.failUpload(id, source, err)
+		p.failUpload(pipeline, models.UploadStepProbe, source, err)
 		return
 	}
+	pipeline.finish(models.UploadStepProbe, probeDetail(result.Media))
 
-	ready := "ready"
-	progress = 100
+	// Playback starts while the ladder is still being written, so a job
+	// whose output can be tracked leaves the transcode step running for
+	// trackUploadOutput to finish.
+	tracked := p.tracksUploadOutput(result.JobID)
+	pipeline.start(models.UploadStepTranscode, transcodeDetail(result))
+	if !tracked {
+		pipeline.finish(models.UploadStepTranscode, transcodeDetail(result))
+	}
+
+	pipeline.start(models.UploadStepThumbnail, "")
+	thumbnailURL := strings.TrimSpace(result.ThumbnailURL)
+	if thumbnailURL != "" {
+		pipeline.finish(models.UploadStepThumbnail, "")
+	} else {
+		pipeline.finish(models.UploadStepThumbnail, "the transcoder did not provide a thumbnail")
+	}
+
+	pipeline.start(models.UploadStepPublish, "")
 	playbackURL := strings.TrimSpace(result.PlaybackURL)
 	if playbackURL == "" {
 		playbackURL = source
 	}
 	completedAt := time.Now().UTC()
 	metadata = map[string]string{"sourceUrl": source}
+	if scan != "" {
+		metadata["malwareScan"] = scan
+	}
 	if result.JobID != "" {
 		metadata["transcodeJobId"] = result.JobID
 	}
@@ -389,19 +527,108 @@ func (p *UploadProcessor) processUpload(id string) {
 		}
 	}
 	metadata["playbackUrl"] = playbackURL
+	if thumbnailURL != "" {
+		metadata["thumbnailUrl"] = thumbnailURL
+	}
+	if media := result.Media; media != nil {
+		if media.DurationSeconds > 0 {
+			metadata["durationSeconds"] = strconv.FormatFloat(media.DurationSeconds, 'f', -1, 64)
+		}
+		if media.Width > 0 && media.Height > 0 {
+			metadata["resolution"] = fmt.Sprintf("%dx%d", media.Width, media.Height)
+		}
+		if media.VideoCodec != "" {
+			metadata["videoCodec"] = media.VideoCodec
+		}
+		if media.AudioCodec != "" {
+			metadata["audioCodec"] = media.AudioCodec
+		}
+		if skipped := ingest.SelectUploadLadder(p.renditions, *media).Skipped; len(skipped) > 0 {
+			metadata["skippedRenditions"] = strings.Join(skipped, ",")
+		}
+	}
 	if _, err := p.store.UpdateUpload(p.ctx, id, storage.UploadUpdate{
-		Status:      &ready,
-		Progress:    &progress,
 		PlaybackURL: &playbackURL,
 		Metadata:    metadata,
+	}); err != nil {
+		p.logger.Error("failed to publish upload", "upload_id", id, "error", err)
+		p.scheduleRetry(id)
+		return
+	}
+	pipeline.finish(models.UploadStepPublish, "")
+
+	// The finalize step lands in the same write that marks the upload ready.
+	ready := "ready"
+	pipeline.apply(models.UploadStep{Name: models.UploadStepFinalize, Status: models.UploadStepDone, StartedAt: &completedAt, FinishedAt: &completedAt})
+	if _, err := p.store.UpdateUpload(p.ctx, id, storage.UploadUpdate{
+		Status:      &ready,
 		CompletedAt: &completedAt,
 		Error:       stringPtr(""),
+		Steps:       pipeline.steps(),
 	}); err != nil {
 		p.logger.Error("failed to mark upload ready", "upload_id", id, "error", err)
 		p.scheduleRetry(id)
 		return
 	}
 	p.logger.Info("upload transcoded", "upload_id", id, "channel_id", upload.ChannelID, "playback_url", playbackURL)
+	if tracked {
+		p.trackUploadOutput(pipeline, result.JobID, transcodeDetail(result))
+	}
+}
+
+// tracksUploadOutput reports whether the transcoder can tell when the upload
+// job jobID has finished writing its output.
+func (p *UploadProcessor) tracksUploadOutput(jobID string) bool {
+	_, ok := p.ingest.(ingest.UploadOutputReporter)
+	return ok && strings.TrimSpace(jobID) != ""
+}
+
+// trackUploadOutput waits in the background for the transcoder to finish
+// writing the upload's renditions and records their size, so the channel's
+// storage usage reflects what the upload occupies rather than its source
+// file. It finishes the transcode step when the job completes or fails, and
+// gives up after the upload timeout or when the transcoder cannot report on
+// the job.
+func (p *UploadProcessor) trackUploadOutput(pipeline *uploadPipeline, jobID, detail string) {
+	reporter, ok := p.ingest.(ingest.UploadOutputReporter)
+	if !ok || strings.TrimSpace(jobID) == "" {
+		return
+	}
+	id := pipeline.id
+	p.wg.Add(1)
+	go func() {
+		defer p.wg.Done()
+		ctx, cancel := context.WithTimeout(p.ctx, p.timeout)
+		defer cancel()
+		ticker := time.NewTicker(p.outputPoll)
+		defer ticker.Stop()
+		for {
+			output, err := reporter.UploadOutput(ctx, jobID)
+			switch {
+			case errors.Is(err, ingest.ErrUploadOutputUnavailable):
+				pipeline.finish(models.UploadStepTranscode, detail)
+				return
+			case err != nil:
+				p.logger.Warn("failed to check upload output", "upload_id", id, "job_id", jobID, "error", err)
+			case output.Status == ingest.UploadOutputCompleted:
+				pipeline.finish(models.UploadStepTranscode, detail)
+				size := output.Bytes
+				if _, err := p.store.UpdateUpload(ctx, id, storage.UploadUpdate{OutputBytes: &size}); err != nil {
+					p.logger.Error("failed to record upload output size", "upload_id", id, "error", err)
+				}
+				return
+			case output.Status == ingest.UploadOutputFailed:
+				p.logger.Warn("upload transcode job failed after the upload was marked ready", "upload_id", id, "job_id", jobID)
+				pipeline.fail(models.UploadStepTranscode, "the transcoder failed to finish the renditions")
+				return
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-ticker.C:
+			}
+		}
+	}()
 }
 
 func (p *UploadProcessor) scheduleRetry(id string) {
@@ -425,27 +652,147 @@ func (p *UploadProcessor) scheduleRetry(id string) {
 	}()
 }
 
-func (p *UploadProcessor) failUpload(id, source string, err error) {
+// failUpload marks the upload failed, attributing the failure to step.
+func (p *UploadProcessor) failUpload(pipeline *uploadPipeline, step, source string, err error) {
 	if p.store == nil {
 		return
 	}
+	id := pipeline.id
 	failed := "failed"
-	progress := 0
 	message := strings.TrimSpace(err.Error())
 	metadata := map[string]string{}
 	if source != "" {
 		metadata["sourceUrl"] = source
 	}
+	var rejection *ingest.UploadRejectedError
+	if errors.As(err, &rejection) {
+		if text := strings.TrimSpace(rejection.Message); text != "" {
+			message = text
+		}
+		if reason := strings.TrimSpace(rejection.Reason); reason != "" {
+			metadata["rejectionReason"] = reason
+		}
+	}
+	var scanErr *uploadScanError
+	if errors.As(err, &scanErr) {
+		message = scanErr.Message
+		metadata["rejectionReason"] = scanErr.Reason
+		for key, value := range scanErr.Metadata {
+			metadata[key] = value
+		}
+	}
+	finishedAt := time.Now().UTC()
+	pipeline.apply(models.UploadStep{Name: step, Status: models.UploadStepFailed, FinishedAt: &finishedAt, Detail: message})
 	if _, updateErr := p.store.UpdateUpload(p.ctx, id, storage.UploadUpdate{
 		Status:   &failed,
-		Progress: &progress,
 		Metadata: metadata,
 		Error:    &message,
+		Steps:    pipeline.steps(),
 	}); updateErr != nil {
 		p.logger.Error("failed to update failed upload", "upload_id", id, "error", updateErr, "failure", err)
 		return
 	}
-	p.logger.Error("upload transcode failed", "upload_id", id, "error", err)
+	p.logger.Error("upload transcode failed", "upload_id", id, "step", step, "error", err)
+}
+
+// uploadPipeline mirrors the steps of the upload being processed and reports
+// each transition to the store. Step writes are informational: a failed
+// write is logged and processing carries on, and the steps are written in
+// full again when the upload is marked ready or failed.
+type uploadPipeline struct {
+	p  *UploadProcessor
+	id string
+
+	mu     sync.Mutex
+	upload models.Upload
+}
+
+func (p *UploadProcessor) newPipeline(id string) *uploadPipeline {
+	return &uploadPipeline{p: p, id: id, upload: models.Upload{Steps: models.UploadSteps()}}
+}
+
+func (pl *uploadPipeline) start(name, detail string) {
+	now := time.Now().UTC()
+	pl.record(models.UploadStep{Name: name, Status: models.UploadStepRunning, StartedAt: &now, Detail: detail})
+}
+
+func (pl *uploadPipeline) finish(name, detail string) {
+	now := time.Now().UTC()
+	pl.record(models.UploadStep{Name: name, Status: models.UploadStepDone, FinishedAt: &now, Detail: detail})
+}
+
+func (pl *uploadPipeline) fail(name, detail string) {
+	now := time.Now().UTC()
+	pl.record(models.UploadStep{Name: name, Status: models.UploadStepFailed, FinishedAt: &now, Detail: detail})
+}
+
+// apply records step locally only, for callers that write the steps along
+// with the rest of an upload update.
+func (pl *uploadPipeline) apply(step models.UploadStep) {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	pl.upload.ApplyStep(step)
+}
+
+func (pl *uploadPipeline) record(step models.UploadStep) {
+	pl.apply(step)
+	if err := pl.p.store.UpdateUploadStep(pl.p.ctx, pl.id, step); err != nil {
+		pl.p.logger.Warn("failed to record upload step", "upload_id", pl.id, "step", step.Name, "status", step.Status, "error", err)
+	}
+}
+
+func (pl *uploadPipeline) steps() []models.UploadStep {
+	pl.mu.Lock()
+	defer pl.mu.Unlock()
+	return append([]models.UploadStep(nil), pl.upload.Steps...)
+}
+
+// probeDetail summarizes the probed source for the probe step.
+func probeDetail(media *ingest.MediaInfo) string {
+	if media == nil {
+		return ""
+	}
+	parts := make([]string, 0, 3)
+	if media.Width > 0 && media.Height > 0 {
+		parts = append(parts, fmt.Sprintf("%dx%d", media.Width, media.Height))
+	}
+	codecs := make([]string, 0, 2)
+	for _, codec := range []string{media.VideoCodec, media.AudioCodec} {
+		if codec != "" {
+			codecs = append(codecs, codec)
+		}
+	}
+	if len(codecs) > 0 {
+		parts = append(parts, strings.Join(codecs, "/"))
+	}
+	if media.DurationSeconds > 0 {
+		parts = append(parts, (time.Duration(media.DurationSeconds * float64(time.Second))).Round(time.Second).String())
+	}
+	return strings.Join(parts, ", ")
+}
+
+// transcodeDetail names the renditions a transcode produces.
+func transcodeDetail(result ingest.UploadTranscodeResult) string {
+	names := make([]string, 0, len(result.Renditions))
+	for _, rendition := range result.Renditions {
+		if name := strings.TrimSpace(rendition.Name); name != "" {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return ""
+	}
+	return "renditions " + strings.Join(names, ", ")
+}
+
+// uploadSourceMedia reads the source frame size recorded on an upload's
+// metadata, if any.
+func uploadSourceMedia(metadata map[string]string) ingest.MediaInfo {
+	width, height, ok := ingest.RenditionDimensions(metadata["resolution"])
+	if !ok {
+		return ingest.MediaInfo{}
+	}
+	return ingest.MediaInfo{Width: width, Height: height}
 }
 
 func stringPtr(s string) *string {
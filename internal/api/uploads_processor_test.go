@@ -3,6 +3,7 @@ package api
 import (
 	"context"
 	"errors"
+	"fmt"
 	"io"
 	"log/slog"
 	"strings"
@@ -139,6 +140,350 @@ func TestUploadProcessorFailUpload(t *testing.T) {
 	})
 }
 
+func TestUploadProcessorRejectedUploadUsesFriendlyMessage(t *testing.T) {
+	store := newFakeUploadStore()
+	store.uploads = map[string]models.Upload{
+		"upload-audio": {
+			ID:        "upload-audio",
+			ChannelID: "channel-1",
+			Status:    "pending",
+			Metadata:  map[string]string{"sourceUrl": "https://example.com/podcast.mp3"},
+		},
+	}
+
+	ingestFake := newFakeIngest()
+	ingestFake.setResult("upload-audio", ingest.UploadTranscodeResult{}, fmt.Errorf("start upload: %w", &ingest.UploadRejectedError{Reason: "not_video", Message: "file is not a video"}))
+	updates := store.updatesFor("upload-audio")
+	done := ingestFake.completion("upload-audio")
+
+	processor := NewUploadProcessor(UploadProcessorConfig{
+		Store:   store,
+		Ingest:  ingestFake,
+		Workers: 1,
+		Timeout: time.Second,
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+
+	processor.Start()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := processor.Shutdown(ctx); err != nil {
+			t.Fatalf("Shutdown error: %v", err)
+		}
+	}()
+
+	processor.Enqueue("upload-audio")
+
+	waitForCompletion(t, done, "upload-audio", time.Second)
+	waitForUploadUpdate(t, updates, time.Second, func(upload models.Upload) bool {
+		return upload.Status == "failed" && upload.Error == "file is not a video" && upload.Metadata["rejectionReason"] == "not_video"
+	})
+}
+
+func TestUploadProcessorStoresProbedMedia(t *testing.T) {
+	store := newFakeUploadStore()
+	store.uploads = map[string]models.Upload{
+		"upload-media": {
+			ID:        "upload-media",
+			ChannelID: "channel-1",
+			Status:    "pending",
+			Metadata:  map[string]string{"sourceUrl": "https://example.com/video.mp4"},
+		},
+	}
+
+	ingestFake := newFakeIngest()
+	ingestFake.setResult("upload-media", ingest.UploadTranscodeResult{
+		PlaybackURL: "https://vod.example.com/video.m3u8",
+		Media: &ingest.MediaInfo{
+			DurationSeconds: 93.5,
+			Width:           1920,
+			Height:          1080,
+			VideoCodec:      "h264",
+			AudioCodec:      "aac",
+		},
+	}, nil)
+	updates := store.updatesFor("upload-media")
+	done := ingestFake.completion("upload-media")
+
+	processor := NewUploadProcessor(UploadProcessorConfig{
+		Store:   store,
+		Ingest:  ingestFake,
+		Workers: 1,
+		Timeout: time.Second,
+		Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+
+	processor.Start()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := processor.Shutdown(ctx); err != nil {
+			t.Fatalf("Shutdown error: %v", err)
+		}
+	}()
+
+	processor.Enqueue("upload-media")
+
+	waitForCompletion(t, done, "upload-media", time.Second)
+	waitForUploadUpdate(t, updates, time.Second, func(upload models.Upload) bool {
+		return upload.Status == "ready" &&
+			upload.Metadata["durationSeconds"] == "93.5" &&
+			upload.Metadata["resolution"] == "1920x1080" &&
+			upload.Metadata["videoCodec"] == "h264" &&
+			upload.Metadata["audioCodec"] == "aac"
+	})
+}
+
+func TestUploadProcessorRecordsOutputSize(t *testing.T) {
+	store := newFakeUploadStore()
+	store.uploads = map[string]models.Upload{
+		"upload-output": {
+			ID:        "upload-output",
+			ChannelID: "channel-1",
+			Status:    "pending",
+			SizeBytes: 1 << 20,
+			Metadata:  map[string]string{"sourceUrl": "https://example.com/video.mp4"},
+		},
+	}
+
+	ingestFake := &outputReportingIngest{fakeIngest: newFakeIngest(), runningPolls: 2, bytes: 4096}
+	ingestFake.setResult("upload-output", ingest.UploadTranscodeResult{JobID: "job-output", PlaybackURL: "https://vod.example.com/video.m3u8"}, nil)
+	updates := store.updatesFor("upload-output")
+
+	processor := NewUploadProcessor(UploadProcessorConfig{
+		Store:              store,
+		Ingest:             ingestFake,
+		Workers:            1,
+		Timeout:            time.Second,
+		OutputPollInterval: time.Millisecond,
+		Logger:             slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+
+	processor.Start()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := processor.Shutdown(ctx); err != nil {
+			t.Fatalf("Shutdown error: %v", err)
+		}
+	}()
+
+	processor.Enqueue("upload-output")
+
+	waitForUploadUpdate(t, updates, time.Second, func(upload models.Upload) bool {
+		return upload.Status == "ready" && upload.OutputBytes == 4096
+	})
+	if jobs := ingestFake.polledJobs(); len(jobs) != 3 || jobs[0] != "job-output" {
+		t.Fatalf("expected the job to be polled until it completed, got %v", jobs)
+	}
+}
+
+func TestUploadProcessorTracksPipelineSteps(t *testing.T) {
+	store := newFakeUploadStore()
+	store.uploads = map[string]models.Upload{
+		"upload-steps": {
+			ID:        "upload-steps",
+			ChannelID: "channel-1",
+			Status:    "pending",
+			Metadata:  map[string]string{"sourceUrl": "https://example.com/video.mp4"},
+		},
+	}
+
+	ingestFake := &outputReportingIngest{fakeIngest: newFakeIngest(), runningPolls: 2, bytes: 2048}
+	ingestFake.setResult("upload-steps", ingest.UploadTranscodeResult{
+		JobID:        "job-steps",
+		PlaybackURL:  "https://vod.example.com/video.m3u8",
+		Renditions:   []ingest.Rendition{{Name: "720p"}, {Name: "480p"}},
+		Media:        &ingest.MediaInfo{DurationSeconds: 93.5, Width: 1920, Height: 1080, VideoCodec: "h264", AudioCodec: "aac"},
+		ThumbnailURL: "https://vod.example.com/poster.jpg",
+	}, nil)
+	updates := store.updatesFor("upload-steps")
+
+	processor := NewUploadProcessor(UploadProcessorConfig{
+		Store:              store,
+		Ingest:             ingestFake,
+		Workers:            1,
+		Timeout:            time.Second,
+		OutputPollInterval: time.Millisecond,
+		Logger:             slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	processor.Start()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := processor.Shutdown(ctx); err != nil {
+			t.Fatalf("Shutdown error: %v", err)
+		}
+	}()
+
+	processor.Enqueue("upload-steps")
+
+	waitForUploadUpdate(t, updates, time.Second, func(upload models.Upload) bool {
+		return upload.Status == "processing" && stepStatus(upload, models.UploadStepProbe) == models.UploadStepRunning
+	})
+	waitForUploadUpdate(t, updates, time.Second, func(upload models.Upload) bool {
+		return upload.Status == "ready" &&
+			stepStatus(upload, models.UploadStepTranscode) == models.UploadStepRunning &&
+			stepStatus(upload, models.UploadStepFinalize) == models.UploadStepDone &&
+			upload.Progress == 40
+	})
+	waitForUploadUpdate(t, updates, time.Second, func(upload models.Upload) bool {
+		return stepStatus(upload, models.UploadStepTranscode) == models.UploadStepDone
+	})
+
+	upload, _ := store.GetUpload(context.Background(), "upload-steps")
+	if upload.Progress != 100 || len(upload.Steps) != 5 {
+		t.Fatalf("expected every step done at 100%%, got %+v", upload)
+	}
+	for _, step := range upload.Steps {
+		if step.Status != models.UploadStepDone || step.StartedAt == nil || step.FinishedAt == nil {
+			t.Fatalf("expected step %s done with start and finish times, got %+v", step.Name, step)
+		}
+		if step.FinishedAt.Before(*step.StartedAt) {
+			t.Fatalf("expected step %s to finish after it started, got %+v", step.Name, step)
+		}
+	}
+	if detail := upload.Steps[0].Detail; detail != "1920x1080, h264/aac, 1m34s" {
+		t.Fatalf("expected the probe to describe the source, got %q", detail)
+	}
+	if detail := upload.Steps[1].Detail; detail != "renditions 720p, 480p" {
+		t.Fatalf("expected the transcode to list its renditions, got %q", detail)
+	}
+	if upload.Metadata["thumbnailUrl"] != "https://vod.example.com/poster.jpg" {
+		t.Fatalf("expected the thumbnail recorded, got %+v", upload.Metadata)
+	}
+}
+
+func TestUploadProcessorAttributesFailureToStep(t *testing.T) {
+	store := newFakeUploadStore()
+	store.uploads = map[string]models.Upload{
+		"upload-rejected": {
+			ID:        "upload-rejected",
+			ChannelID: "channel-1",
+			Status:    "pending",
+			Metadata:  map[string]string{"sourceUrl": "https://example.com/podcast.mp3"},
+		},
+		"upload-broken": {
+			ID:        "upload-broken",
+			ChannelID: "channel-1",
+			Status:    "pending",
+			Metadata:  map[string]string{"sourceUrl": "https://example.com/video.mp4"},
+		},
+	}
+
+	ingestFake := &outputReportingIngest{fakeIngest: newFakeIngest(), fail: true}
+	ingestFake.setResult("upload-rejected", ingest.UploadTranscodeResult{}, &ingest.UploadRejectedError{Reason: "not_video", Message: "file is not a video"})
+	ingestFake.setResult("upload-broken", ingest.UploadTranscodeResult{JobID: "job-broken", PlaybackURL: "https://vod.example.com/broken.m3u8"}, nil)
+	rejectedUpdates := store.updatesFor("upload-rejected")
+	brokenUpdates := store.updatesFor("upload-broken")
+
+	processor := NewUploadProcessor(UploadProcessorConfig{
+		Store:              store,
+		Ingest:             ingestFake,
+		Workers:            1,
+		Timeout:            time.Second,
+		OutputPollInterval: time.Millisecond,
+		Logger:             slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+	processor.Start()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := processor.Shutdown(ctx); err != nil {
+			t.Fatalf("Shutdown error: %v", err)
+		}
+	}()
+
+	processor.Enqueue("upload-rejected")
+	processor.Enqueue("upload-broken")
+
+	waitForUploadUpdate(t, rejectedUpdates, time.Second, func(upload models.Upload) bool {
+		return upload.Status == "failed"
+	})
+	rejected, _ := store.GetUpload(context.Background(), "upload-rejected")
+	failed, ok := rejected.FailedStep()
+	if !ok || failed.Name != models.UploadStepProbe || failed.Detail != "file is not a video" || failed.StartedAt == nil {
+		t.Fatalf("expected the rejection attributed to the probe, got %+v (ok %v)", failed, ok)
+	}
+	if rejected.Progress != 0 || stepStatus(rejected, models.UploadStepTranscode) != models.UploadStepPending {
+		t.Fatalf("expected the later steps left pending at 0%%, got %+v", rejected)
+	}
+
+	waitForUploadUpdate(t, brokenUpdates, time.Second, func(upload models.Upload) bool {
+		return stepStatus(upload, models.UploadStepTranscode) == models.UploadStepFailed
+	})
+	broken, _ := store.GetUpload(context.Background(), "upload-broken")
+	failed, ok = broken.FailedStep()
+	if !ok || failed.Name != models.UploadStepTranscode {
+		t.Fatalf("expected the failed job attributed to the transcode, got %+v (ok %v)", failed, ok)
+	}
+	if broken.Progress != 40 || stepStatus(broken, models.UploadStepProbe) != models.UploadStepDone {
+		t.Fatalf("expected the other steps to keep their progress, got %+v", broken)
+	}
+}
+
+// stepStatus returns the status of the named step of upload.
+func stepStatus(upload models.Upload, name string) string {
+	for _, step := range upload.Steps {
+		if step.Name == name {
+			return step.Status
+		}
+	}
+	return ""
+}
+
+func TestUploadProcessorFitsLadderToSource(t *testing.T) {
+	store := newFakeUploadStore()
+	store.uploads = map[string]models.Upload{
+		"upload-small": {
+			ID:        "upload-small",
+			ChannelID: "channel-1",
+			Status:    "pending",
+			Metadata:  map[string]string{"sourceUrl": "https://example.com/small.mp4", "resolution": "854x480"},
+		},
+	}
+
+	ingestFake := newFakeIngest()
+	ingestFake.setResult("upload-small", ingest.UploadTranscodeResult{
+		PlaybackURL: "https://vod.example.com/small.m3u8",
+		Renditions:  []ingest.Rendition{{Name: "480p"}},
+		Media:       &ingest.MediaInfo{Width: 854, Height: 480, VideoCodec: "h264"},
+	}, nil)
+	updates := store.updatesFor("upload-small")
+	done := ingestFake.completion("upload-small")
+
+	processor := NewUploadProcessor(UploadProcessorConfig{
+		Store:      store,
+		Ingest:     ingestFake,
+		Renditions: []ingest.Rendition{{Name: "1080p", Bitrate: 6000}, {Name: "720p", Bitrate: 3000}, {Name: "480p", Bitrate: 1500}},
+		Workers:    1,
+		Timeout:    time.Second,
+		Logger:     slog.New(slog.NewTextHandler(io.Discard, nil)),
+	})
+
+	processor.Start()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := processor.Shutdown(ctx); err != nil {
+			t.Fatalf("Shutdown error: %v", err)
+		}
+	}()
+
+	processor.Enqueue("upload-small")
+
+	waitForCompletion(t, done, "upload-small", time.Second)
+	if requested := ingestFake.requestedRenditions("upload-small"); len(requested) != 1 || requested[0].Name != "480p" {
+		t.Fatalf("expected only the 480p rendition to be requested, got %+v", requested)
+	}
+	waitForUploadUpdate(t, updates, time.Second, func(upload models.Upload) bool {
+		return upload.Status == "ready" &&
+			upload.Metadata["renditions"] == "480p" &&
+			upload.Metadata["skippedRenditions"] == "1080p,720p"
+	})
+}
+
 func TestUploadProcessorTimeout(t *testing.T) {
 	store := newFakeUploadStore()
 	store.uploads = map[string]models.Upload{
@@ -301,7 +646,7 @@ func (f *fakeUploadStore) updatesFor(id string) <-chan models.Upload {
 	defer f.mu.Unlock()
 	ch, ok := f.updateCh[id]
 	if !ok {
-		ch = make(chan models.Upload, 8)
+		ch = make(chan models.Upload, 32)
 		f.updateCh[id] = ch
 	}
 	return ch
@@ -326,6 +671,10 @@ func (f *fakeUploadStore) UpdateUpload(ctx context.Context, id string, update st
 		delete(f.failFirstUpdate, id)
 		return models.Upload{}, err
 	}
+	if update.Steps != nil {
+		upload.Steps = append([]models.UploadStep(nil), update.Steps...)
+		upload.Progress = models.UploadProgress(upload.Steps)
+	}
 	if update.Status != nil {
 		upload.Status = *update.Status
 	}
@@ -344,6 +693,9 @@ func (f *fakeUploadStore) UpdateUpload(ctx context.Context, id string, update st
 	if update.CompletedAt != nil {
 		upload.CompletedAt = update.CompletedAt
 	}
+	if update.OutputBytes != nil {
+		upload.OutputBytes = *update.OutputBytes
+	}
 	f.uploads[id] = upload
 	if ch, ok := f.updateCh[id]; ok {
 		select {
@@ -354,6 +706,31 @@ func (f *fakeUploadStore) UpdateUpload(ctx context.Context, id string, update st
 	return cloneUpload(upload), nil
 }
 
+func (f *fakeUploadStore) UpdateUploadStep(ctx context.Context, id string, step models.UploadStep) error {
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	default:
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	upload, ok := f.uploads[id]
+	if !ok {
+		return errors.New("upload not found")
+	}
+	upload = cloneUpload(upload)
+	upload.ApplyStep(step)
+	f.uploads[id] = upload
+	if ch, ok := f.updateCh[id]; ok {
+		select {
+		case ch <- cloneUpload(upload):
+		default:
+		}
+	}
+	return nil
+}
+
 func (f *fakeUploadStore) failFirstUpdateFor(id string, err error) {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -383,6 +760,7 @@ func cloneMetadata(src map[string]string) map[string]string {
 
 func cloneUpload(upload models.Upload) models.Upload {
 	upload.Metadata = cloneMetadata(upload.Metadata)
+	upload.Steps = append([]models.UploadStep(nil), upload.Steps...)
 	return upload
 }
 
@@ -395,6 +773,7 @@ type fakeIngest struct {
 	delays    map[string]time.Duration
 	callTotal map[string]int
 	done      map[string]chan struct{}
+	requested map[string][]ingest.Rendition
 }
 
 func newFakeIngest() *fakeIngest {
@@ -404,6 +783,7 @@ func newFakeIngest() *fakeIngest {
 		delays:    make(map[string]time.Duration),
 		callTotal: make(map[string]int),
 		done:      make(map[string]chan struct{}),
+		requested: make(map[string][]ingest.Rendition),
 	}
 }
 
@@ -424,6 +804,12 @@ func (f *fakeIngest) setDelay(id string, delay time.Duration) {
 	f.delays[id] = delay
 }
 
+func (f *fakeIngest) requestedRenditions(id string) []ingest.Rendition {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return ingest.CloneRenditions(f.requested[id])
+}
+
 func (f *fakeIngest) callCount(id string) int {
 	f.mu.Lock()
 	defer f.mu.Unlock()
@@ -458,6 +844,7 @@ func (f *fakeIngest) signalComplete(id string) {
 func (f *fakeIngest) TranscodeUpload(ctx context.Context, params ingest.UploadTranscodeParams) (ingest.UploadTranscodeResult, error) {
 	f.mu.Lock()
 	f.callTotal[params.UploadID]++
+	f.requested[params.UploadID] = ingest.CloneRenditions(params.Renditions)
 	delay := f.delays[params.UploadID]
 	result, hasResult := f.results[params.UploadID]
 	err := f.errors[params.UploadID]
@@ -486,6 +873,40 @@ func (f *fakeIngest) TranscodeUpload(ctx context.Context, params ingest.UploadTr
 
 var _ UploadIngestClient = (*fakeIngest)(nil)
 
+// outputReportingIngest reports upload jobs as running for runningPolls
+// checks and then as completed with bytes of output, or as failed when fail
+// is set.
+type outputReportingIngest struct {
+	*fakeIngest
+	runningPolls int
+	bytes        int64
+	fail         bool
+
+	pollMu sync.Mutex
+	polled []string
+}
+
+func (f *outputReportingIngest) UploadOutput(ctx context.Context, jobID string) (ingest.UploadOutput, error) {
+	f.pollMu.Lock()
+	defer f.pollMu.Unlock()
+	f.polled = append(f.polled, jobID)
+	if len(f.polled) <= f.runningPolls {
+		return ingest.UploadOutput{Status: ingest.UploadOutputRunning}, nil
+	}
+	if f.fail {
+		return ingest.UploadOutput{Status: ingest.UploadOutputFailed}, nil
+	}
+	return ingest.UploadOutput{Status: ingest.UploadOutputCompleted, Bytes: f.bytes}, nil
+}
+
+func (f *outputReportingIngest) polledJobs() []string {
+	f.pollMu.Lock()
+	defer f.pollMu.Unlock()
+	return append([]string(nil), f.polled...)
+}
+
+var _ ingest.UploadOutputReporter = (*outputReportingIngest)(nil)
+
 func waitForCompletion(t *testing.T, done <-chan struct{}, id string, timeout time.Duration) {
 	t.Helper()
 	select {
@@ -0,0 +1,275 @@
+package api
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/malware"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+// UploadSources reads the files behind uploads so they can be scanned, and
+// quarantines the ones the scanner flags. Keys are the "mediaPath" recorded
+// on an upload's metadata.
+type UploadSources interface {
+	Open(ctx context.Context, key string) (io.ReadCloser, error)
+	// Quarantine moves the file somewhere it can no longer be served or
+	// transcoded and returns its new key. It never deletes the file, so an
+	// operator can inspect it later.
+	Quarantine(ctx context.Context, key string) (string, error)
+}
+
+// UploadSecurityEvents records the security event raised for an infected
+// upload.
+type UploadSecurityEvents interface {
+	CreateSecurityEvent(params storage.SecurityEventParams) (models.SecurityEvent, error)
+}
+
+// Rejection reasons recorded on uploads that fail the malware scan.
+const (
+	uploadRejectedMalware         = "malware_detected"
+	uploadRejectedScanUnavailable = "scan_unavailable"
+)
+
+// Values of the "malwareScan" metadata recorded on processed uploads.
+const (
+	uploadScanClean   = "clean"
+	uploadScanSkipped = "skipped"
+)
+
+const (
+	defaultUploadScanTimeout = 2 * time.Minute
+	uploadQuarantineDir      = "quarantine"
+)
+
+var errUploadSourceUnscannable = errors.New("upload has no file or URL to scan")
+
+// uploadScanError fails an upload on the scan's account. failUpload records
+// Reason as the upload's rejection reason, shows Message to the uploader,
+// and merges Metadata into the upload's metadata.
+type uploadScanError struct {
+	Reason   string
+	Message  string
+	Metadata map[string]string
+	Err      error
+}
+
+func (e *uploadScanError) Error() string {
+	if e.Err == nil {
+		return e.Message
+	}
+	return fmt.Sprintf("%s: %v", e.Message, e.Err)
+}
+
+func (e *uploadScanError) Unwrap() error {
+	return e.Err
+}
+
+// dirUploadSources serves uploads stored as files in one directory, which
+// is how the upload handlers keep them. Quarantined files move to its
+// quarantine subdirectory.
+type dirUploadSources struct {
+	dir string
+}
+
+// DirUploadSources returns UploadSources for upload files kept in dir.
+func DirUploadSources(dir string) UploadSources {
+	return dirUploadSources{dir: dir}
+}
+
+// UploadSources returns the sources the handler stores uploaded files in,
+// for the upload processor to scan.
+func (h *Handler) UploadSources() UploadSources {
+	return DirUploadSources(h.uploadMediaDir())
+}
+
+func (s dirUploadSources) path(key string) (string, error) {
+	key = filepath.Clean(strings.TrimSpace(key))
+	if key == "." || !filepath.IsLocal(key) {
+		return "", fmt.Errorf("invalid upload source key %q", key)
+	}
+	return filepath.Join(s.dir, key), nil
+}
+
+func (s dirUploadSources) Open(_ context.Context, key string) (io.ReadCloser, error) {
+	fullPath, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	return os.Open(fullPath)
+}
+
+func (s dirUploadSources) Quarantine(_ context.Context, key string) (string, error) {
+	fullPath, err := s.path(key)
+	if err != nil {
+		return "", err
+	}
+	quarantined := path.Join(uploadQuarantineDir, filepath.Base(fullPath))
+	target := filepath.Join(s.dir, filepath.FromSlash(quarantined))
+	if err := os.MkdirAll(filepath.Dir(target), 0o700); err != nil {
+		return "", fmt.Errorf("create quarantine directory: %w", err)
+	}
+	if err := os.Rename(fullPath, target); err != nil {
+		return "", fmt.Errorf("quarantine %s: %w", key, err)
+	}
+	return quarantined, nil
+}
+
+// scansUploads reports whether a scanner is configured.
+func (p *UploadProcessor) scansUploads() bool {
+	if p.scanner == nil {
+		return false
+	}
+	_, noop := p.scanner.(malware.NoopScanner)
+	return !noop
+}
+
+// scanUpload scans the upload before it reaches the transcoder and returns
+// the "malwareScan" metadata to publish with it. A stored file is read from
+// the upload sources; an upload imported from a URL is fetched from source.
+// An infected file is quarantined and the returned error fails the upload.
+// A scan that cannot finish fails the upload too, unless the processor
+// fails open, in which case the upload carries on unscanned.
+func (p *UploadProcessor) scanUpload(upload models.Upload, source string) (string, error) {
+	if !p.scansUploads() {
+		return "", nil
+	}
+	key := strings.TrimSpace(upload.Metadata["mediaPath"])
+	var (
+		result malware.Result
+		err    error
+	)
+	switch {
+	case key != "" && p.sources != nil:
+		result, err = p.scanSource(key)
+	case key == "" && source != "":
+		result, err = p.scanURL(source)
+	default:
+		err = errUploadSourceUnscannable
+	}
+	if err != nil {
+		if p.ctx.Err() != nil {
+			return "", p.ctx.Err()
+		}
+		if p.scanFailOpen {
+			p.logger.Warn("upload not scanned for malware; processing it anyway", "upload_id", upload.ID, "channel_id", upload.ChannelID, "error", err)
+			return uploadScanSkipped, nil
+		}
+		return "", &uploadScanError{
+			Reason:  uploadRejectedScanUnavailable,
+			Message: "the file could not be scanned for malware; try uploading it again later",
+			Err:     err,
+		}
+	}
+	if !result.Infected() {
+		return uploadScanClean, nil
+	}
+	return "", p.quarantineUpload(upload, key, result)
+}
+
+func (p *UploadProcessor) scanSource(key string) (malware.Result, error) {
+	ctx, cancel := context.WithTimeout(p.ctx, p.scanTimeout)
+	defer cancel()
+	file, err := p.sources.Open(ctx, key)
+	if err != nil {
+		return malware.Result{}, fmt.Errorf("open upload source: %w", err)
+	}
+	defer file.Close()
+	result, err := p.scanner.Scan(ctx, key, file)
+	if err != nil && ctx.Err() != nil && !errors.Is(err, ctx.Err()) {
+		err = fmt.Errorf("%w: %w", ctx.Err(), err)
+	}
+	return result, err
+}
+
+// scanURL fetches an upload imported from a URL and streams it to the
+// scanner. The fetch counts towards the scan timeout.
+func (p *UploadProcessor) scanURL(source string) (malware.Result, error) {
+	parsed, err := url.Parse(source)
+	if err != nil || (parsed.Scheme != "http" && parsed.Scheme != "https") || parsed.Host == "" {
+		return malware.Result{}, fmt.Errorf("%w: source is not an http or https URL", errUploadSourceUnscannable)
+	}
+	ctx, cancel := context.WithTimeout(p.ctx, p.scanTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, source, nil)
+	if err != nil {
+		return malware.Result{}, fmt.Errorf("fetch upload source: %w", err)
+	}
+	resp, err := p.sourceClient.Do(req)
+	if err != nil {
+		return malware.Result{}, fmt.Errorf("fetch upload source: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return malware.Result{}, fmt.Errorf("fetch upload source: unexpected status %d", resp.StatusCode)
+	}
+	// The query string may carry credentials, so the scanner only sees the
+	// host and path.
+	name := parsed.Host + parsed.EscapedPath()
+	result, err := p.scanner.Scan(ctx, name, resp.Body)
+	if err != nil && ctx.Err() != nil && !errors.Is(err, ctx.Err()) {
+		err = fmt.Errorf("%w: %w", ctx.Err(), err)
+	}
+	return result, err
+}
+
+// quarantineUpload moves an infected upload's stored file aside, records
+// the security event and audit record, and returns the error that fails
+// the upload. The upload's media token is revoked even when the move fails,
+// so the file can no longer be fetched. An upload imported from a URL has
+// no stored file, so it is only rejected.
+func (p *UploadProcessor) quarantineUpload(upload models.Upload, key string, result malware.Result) error {
+	signature := strings.TrimSpace(result.Signature)
+	if signature == "" {
+		signature = "unknown"
+	}
+	if key == "" {
+		return p.reportInfectedUpload(upload, signature, "", &uploadScanError{
+			Reason:  uploadRejectedMalware,
+			Message: "the file failed the malware scan",
+			Err:     fmt.Errorf("matched %s", signature),
+		})
+	}
+	metadata := map[string]string{"mediaToken": ""}
+	quarantined, err := p.sources.Quarantine(p.ctx, key)
+	if err != nil {
+		p.logger.Error("failed to quarantine infected upload", "upload_id", upload.ID, "media_path", key, "error", err)
+	} else {
+		metadata["mediaPath"] = ""
+		metadata["quarantinePath"] = quarantined
+	}
+	return p.reportInfectedUpload(upload, signature, quarantined, &uploadScanError{
+		Reason:   uploadRejectedMalware,
+		Message:  "the file failed the malware scan and was quarantined",
+		Metadata: metadata,
+		Err:      fmt.Errorf("matched %s", signature),
+	})
+}
+
+// reportInfectedUpload records the security event and audit record for an
+// infected upload and returns failure.
+func (p *UploadProcessor) reportInfectedUpload(upload models.Upload, signature, quarantined string, failure *uploadScanError) error {
+	now := time.Now().UTC()
+	if p.securityEvents != nil {
+		if _, err := p.securityEvents.CreateSecurityEvent(storage.SecurityEventParams{
+			ChannelID: upload.ChannelID,
+			Kind:      models.SecurityEventUploadInfected,
+			Detail:    fmt.Sprintf("upload %s (%s) matched %s", upload.ID, upload.Filename, signature),
+			At:        now,
+		}); err != nil {
+			p.logger.Warn("failed to record infected upload", "upload_id", upload.ID, "channel_id", upload.ChannelID, "error", err)
+		}
+	}
+	p.auditLogger.Info("audit", "action", "upload.quarantine", "upload_id", upload.ID, "channel_id", upload.ChannelID, "signature", signature, "quarantine_path", quarantined, "at", now.Format(time.RFC3339Nano))
+	return failure
+}
@@ -0,0 +1,423 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/ingest"
+	"bitriver-live/internal/malware"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+// fakeScanner flags files whose content contains infectedMarker. It
+// returns err instead when set, and blocks until ctx ends when hang is set.
+type fakeScanner struct {
+	mu      sync.Mutex
+	err     error
+	hang    bool
+	scanned []string
+}
+
+const infectedMarker = "EICAR"
+
+func (f *fakeScanner) Scan(ctx context.Context, name string, r io.Reader) (malware.Result, error) {
+	f.mu.Lock()
+	f.scanned = append(f.scanned, name)
+	err, hang := f.err, f.hang
+	f.mu.Unlock()
+	if hang {
+		<-ctx.Done()
+		return malware.Result{}, ctx.Err()
+	}
+	if err != nil {
+		return malware.Result{}, err
+	}
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return malware.Result{}, err
+	}
+	if bytes.Contains(content, []byte(infectedMarker)) {
+		return malware.Result{Verdict: malware.VerdictInfected, Signature: "Eicar-Test-Signature"}, nil
+	}
+	return malware.Result{Verdict: malware.VerdictClean}, nil
+}
+
+func (f *fakeScanner) scannedNames() []string {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]string(nil), f.scanned...)
+}
+
+// fakeUploadSources keeps upload files in memory, keyed like the handler's
+// media directory.
+type fakeUploadSources struct {
+	mu          sync.Mutex
+	objects     map[string][]byte
+	quarantined map[string][]byte
+}
+
+func newFakeUploadSources(objects map[string]string) *fakeUploadSources {
+	sources := &fakeUploadSources{objects: make(map[string][]byte), quarantined: make(map[string][]byte)}
+	for key, content := range objects {
+		sources.objects[key] = []byte(content)
+	}
+	return sources
+}
+
+func (f *fakeUploadSources) Open(ctx context.Context, key string) (io.ReadCloser, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	content, ok := f.objects[key]
+	if !ok {
+		return nil, os.ErrNotExist
+	}
+	return io.NopCloser(bytes.NewReader(content)), nil
+}
+
+func (f *fakeUploadSources) Quarantine(ctx context.Context, key string) (string, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	content, ok := f.objects[key]
+	if !ok {
+		return "", os.ErrNotExist
+	}
+	delete(f.objects, key)
+	quarantined := "quarantine/" + key
+	f.quarantined[quarantined] = content
+	return quarantined, nil
+}
+
+type fakeSecurityEvents struct {
+	mu     sync.Mutex
+	events []storage.SecurityEventParams
+}
+
+func (f *fakeSecurityEvents) CreateSecurityEvent(params storage.SecurityEventParams) (models.SecurityEvent, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.events = append(f.events, params)
+	return models.SecurityEvent{ChannelID: params.ChannelID, Kind: params.Kind, Detail: params.Detail}, nil
+}
+
+func (f *fakeSecurityEvents) recorded() []storage.SecurityEventParams {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	return append([]storage.SecurityEventParams(nil), f.events...)
+}
+
+func TestUploadProcessorScansBeforeTranscoding(t *testing.T) {
+	tests := []struct {
+		name     string
+		content  string
+		metadata map[string]string
+		scanner  *fakeScanner
+		failOpen bool
+		// imported serves content over HTTP with importStatus, or 200, as
+		// the source URL of an upload with no stored file.
+		imported     bool
+		importStatus int
+		// wantReason is the rejection reason of a failed upload; empty
+		// means the upload is transcoded.
+		wantReason string
+		wantScan   string
+	}{
+		{name: "clean file is transcoded", content: "video", wantScan: "clean"},
+		{name: "infected file is quarantined", content: "video " + infectedMarker, wantReason: "malware_detected"},
+		{name: "scanner error fails closed", content: "video", scanner: &fakeScanner{err: errors.New("connection refused")}, wantReason: "scan_unavailable"},
+		{name: "scanner error fails open", content: "video", scanner: &fakeScanner{err: errors.New("connection refused")}, failOpen: true, wantScan: "skipped"},
+		{name: "scan timeout fails closed", content: "video", scanner: &fakeScanner{hang: true}, wantReason: "scan_unavailable"},
+		{name: "scan timeout fails open", content: "video", scanner: &fakeScanner{hang: true}, failOpen: true, wantScan: "skipped"},
+		{name: "clean imported URL is transcoded", content: "video", imported: true, wantScan: "clean"},
+		{name: "infected imported URL is rejected", content: "video " + infectedMarker, imported: true, wantReason: "malware_detected"},
+		{name: "unreachable imported URL fails closed", imported: true, importStatus: http.StatusNotFound, wantReason: "scan_unavailable"},
+		{name: "unreachable imported URL fails open", imported: true, importStatus: http.StatusNotFound, failOpen: true, wantScan: "skipped"},
+		{name: "non-HTTP imported URL fails closed", metadata: map[string]string{"sourceUrl": "file:///etc/passwd"}, wantReason: "scan_unavailable"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			metadata := tt.metadata
+			if tt.imported {
+				server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+					if tt.importStatus != 0 {
+						w.WriteHeader(tt.importStatus)
+						return
+					}
+					_, _ = io.WriteString(w, tt.content)
+				}))
+				defer server.Close()
+				metadata = map[string]string{"sourceUrl": server.URL + "/vod.mp4?signature=secret"}
+			}
+			if metadata == nil {
+				metadata = map[string]string{"sourceUrl": "https://live.example.com/api/uploads/upload-1/media?token=secret", "mediaPath": "upload-1.mp4", "mediaToken": "secret"}
+			}
+			store := newFakeUploadStore()
+			store.uploads["upload-1"] = models.Upload{ID: "upload-1", ChannelID: "channel-1", Filename: "vod.mp4", Status: "pending", Metadata: metadata}
+			updates := store.updatesFor("upload-1")
+			ingestFake := newFakeIngest()
+			ingestFake.setResult("upload-1", ingest.UploadTranscodeResult{PlaybackURL: "https://vod.example.com/upload-1/index.m3u8"}, nil)
+			scanner := tt.scanner
+			if scanner == nil {
+				scanner = &fakeScanner{}
+			}
+			sources := newFakeUploadSources(map[string]string{"upload-1.mp4": tt.content})
+			events := &fakeSecurityEvents{}
+			var audit bytes.Buffer
+
+			processor := NewUploadProcessor(UploadProcessorConfig{
+				Store:          store,
+				Ingest:         ingestFake,
+				Workers:        1,
+				Timeout:        time.Second,
+				Logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+				Scanner:        scanner,
+				ScanTimeout:    50 * time.Millisecond,
+				ScanFailOpen:   tt.failOpen,
+				Sources:        sources,
+				SecurityEvents: events,
+				AuditLogger:    slog.New(slog.NewTextHandler(&audit, nil)),
+			})
+			processor.Start()
+			defer func() {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+				defer cancel()
+				if err := processor.Shutdown(ctx); err != nil {
+					t.Fatalf("Shutdown error: %v", err)
+				}
+			}()
+			processor.Enqueue("upload-1")
+
+			var final models.Upload
+			waitForUploadUpdate(t, updates, 2*time.Second, func(upload models.Upload) bool {
+				final = upload
+				return upload.Status == "ready" || upload.Status == "failed"
+			})
+
+			if tt.wantReason == "" {
+				if final.Status != "ready" || final.Metadata["malwareScan"] != tt.wantScan {
+					t.Fatalf("expected a ready upload scanned %q, got %+v", tt.wantScan, final)
+				}
+				if ingestFake.callCount("upload-1") != 1 {
+					t.Fatalf("expected the upload handed to the transcoder once, got %d", ingestFake.callCount("upload-1"))
+				}
+				return
+			}
+			if final.Status != "failed" || final.Metadata["rejectionReason"] != tt.wantReason || final.Error == "" {
+				t.Fatalf("expected a failed upload with reason %q, got %+v", tt.wantReason, final)
+			}
+			if stepStatus(final, models.UploadStepProbe) != models.UploadStepFailed {
+				t.Fatalf("expected the probe step failed, got %+v", final.Steps)
+			}
+			if ingestFake.callCount("upload-1") != 0 {
+				t.Fatal("expected the upload kept from the transcoder")
+			}
+			if tt.wantReason != "malware_detected" {
+				if len(sources.quarantined) != 0 || len(events.recorded()) != 0 {
+					t.Fatal("expected an unscanned upload neither quarantined nor reported")
+				}
+				return
+			}
+			recorded := events.recorded()
+			if len(recorded) != 1 || recorded[0].Kind != models.SecurityEventUploadInfected || recorded[0].ChannelID != "channel-1" || !strings.Contains(recorded[0].Detail, "Eicar-Test-Signature") {
+				t.Fatalf("expected one upload_infected event, got %+v", recorded)
+			}
+			if !strings.Contains(audit.String(), "action=upload.quarantine") || !strings.Contains(audit.String(), "upload_id=upload-1") {
+				t.Fatalf("expected a quarantine audit record, got %q", audit.String())
+			}
+			if tt.imported {
+				if final.Error != "the file failed the malware scan" || final.Metadata["quarantinePath"] != "" || len(sources.quarantined) != 0 {
+					t.Fatalf("expected the imported upload rejected without a quarantine, got %+v", final)
+				}
+				if names := scanner.scannedNames(); len(names) != 1 || strings.Contains(names[0], "secret") {
+					t.Fatalf("expected the source scanned once without its query string, got %v", names)
+				}
+				return
+			}
+
+			if final.Error != "the file failed the malware scan and was quarantined" {
+				t.Fatalf("unexpected error message %q", final.Error)
+			}
+			if final.Metadata["quarantinePath"] != "quarantine/upload-1.mp4" || final.Metadata["mediaPath"] != "" || final.Metadata["mediaToken"] != "" {
+				t.Fatalf("expected the media moved to quarantine and its token revoked, got %v", final.Metadata)
+			}
+			if _, ok := sources.objects["upload-1.mp4"]; ok {
+				t.Fatal("expected the source moved out of the media store")
+			}
+			if content := string(sources.quarantined["quarantine/upload-1.mp4"]); content != tt.content {
+				t.Fatalf("expected the source kept in quarantine, got %q", content)
+			}
+		})
+	}
+}
+
+func TestUploadProcessorSkipsScanWithoutScanner(t *testing.T) {
+	for name, scanner := range map[string]malware.Scanner{"nil": nil, "noop": malware.NoopScanner{}} {
+		t.Run(name, func(t *testing.T) {
+			store := newFakeUploadStore()
+			store.uploads["upload-1"] = models.Upload{ID: "upload-1", ChannelID: "channel-1", Status: "pending", Metadata: map[string]string{"sourceUrl": "https://example.com/vod.mp4"}}
+			updates := store.updatesFor("upload-1")
+			ingestFake := newFakeIngest()
+			ingestFake.setResult("upload-1", ingest.UploadTranscodeResult{PlaybackURL: "https://vod.example.com/upload-1/index.m3u8"}, nil)
+
+			processor := NewUploadProcessor(UploadProcessorConfig{
+				Store:   store,
+				Ingest:  ingestFake,
+				Workers: 1,
+				Timeout: time.Second,
+				Logger:  slog.New(slog.NewTextHandler(io.Discard, nil)),
+				Scanner: scanner,
+			})
+			processor.Start()
+			defer func() {
+				ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+				defer cancel()
+				if err := processor.Shutdown(ctx); err != nil {
+					t.Fatalf("Shutdown error: %v", err)
+				}
+			}()
+			processor.Enqueue("upload-1")
+
+			waitForUploadUpdate(t, updates, time.Second, func(upload models.Upload) bool {
+				if upload.Status == "failed" {
+					t.Fatalf("expected the upload processed unscanned, got %+v", upload)
+				}
+				return upload.Status == "ready" && upload.Metadata["malwareScan"] == ""
+			})
+		})
+	}
+}
+
+func TestDirUploadSourcesQuarantine(t *testing.T) {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "upload-1.mp4"), []byte("payload"), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+	sources := DirUploadSources(dir)
+
+	quarantined, err := sources.Quarantine(context.Background(), "upload-1.mp4")
+	if err != nil {
+		t.Fatalf("Quarantine: %v", err)
+	}
+	if quarantined != "quarantine/upload-1.mp4" {
+		t.Fatalf("unexpected quarantine key %q", quarantined)
+	}
+	if _, err := os.Stat(filepath.Join(dir, "upload-1.mp4")); !errors.Is(err, os.ErrNotExist) {
+		t.Fatalf("expected the original removed, stat returned %v", err)
+	}
+	file, err := sources.Open(context.Background(), quarantined)
+	if err != nil {
+		t.Fatalf("Open quarantined: %v", err)
+	}
+	defer file.Close()
+	if content, _ := io.ReadAll(file); string(content) != "payload" {
+		t.Fatalf("expected the quarantined file intact, got %q", content)
+	}
+
+	for _, key := range []string{"", "../outside.mp4", "/etc/passwd"} {
+		if _, err := sources.Open(context.Background(), key); err == nil {
+			t.Fatalf("expected Open to reject key %q", key)
+		}
+		if _, err := sources.Quarantine(context.Background(), key); err == nil {
+			t.Fatalf("expected Quarantine to reject key %q", key)
+		}
+	}
+}
+
+func TestInfectedUploadSurfacesThroughAPI(t *testing.T) {
+	h, store := newTestHandler(t)
+	h.UploadMediaDir = t.TempDir()
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Creator", Email: "creator@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Main", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	rec := postUploadFile(t, h, owner, channel.ID, []byte("video "+infectedMarker), nil)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected 201, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created uploadResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode upload: %v", err)
+	}
+	mediaPath, token := created.Metadata["mediaPath"], created.Metadata["mediaToken"]
+
+	ingestFake := newFakeIngest()
+	processor := NewUploadProcessor(UploadProcessorConfig{
+		Store:          RepositoryUploadStore(store),
+		Ingest:         ingestFake,
+		Workers:        1,
+		Timeout:        time.Second,
+		Logger:         slog.New(slog.NewTextHandler(io.Discard, nil)),
+		Scanner:        &fakeScanner{},
+		Sources:        h.UploadSources(),
+		SecurityEvents: store,
+	})
+	processor.Start()
+	defer func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		if err := processor.Shutdown(ctx); err != nil {
+			t.Fatalf("Shutdown error: %v", err)
+		}
+	}()
+	processor.Enqueue(created.ID)
+
+	var resp uploadResponse
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		req := httptest.NewRequest(http.MethodGet, "/api/uploads/"+created.ID, nil)
+		rec := httptest.NewRecorder()
+		h.UploadByID(rec, withUser(req, owner))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		resp = uploadResponse{}
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode upload: %v", err)
+		}
+		if resp.Status == "failed" {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("upload not failed in time: %+v", resp)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	if resp.Error != "the file failed the malware scan and was quarantined" || resp.FailedStep != models.UploadStepProbe || resp.Metadata["rejectionReason"] != "malware_detected" {
+		t.Fatalf("expected the malware failure surfaced, got %+v", resp)
+	}
+	if _, ok := resp.Metadata["mediaToken"]; ok {
+		t.Fatalf("expected the media token revoked, got %v", resp.Metadata)
+	}
+	if _, err := os.Stat(filepath.Join(h.UploadMediaDir, "quarantine", mediaPath)); err != nil {
+		t.Fatalf("expected the file quarantined: %v", err)
+	}
+	media := httptest.NewRecorder()
+	h.UploadByID(media, httptest.NewRequest(http.MethodGet, "/api/uploads/"+created.ID+"/media?token="+token, nil))
+	if media.Code != http.StatusForbidden {
+		t.Fatalf("expected the old media link refused, got %d", media.Code)
+	}
+	events, err := store.ListSecurityEvents(channel.ID, time.Time{})
+	if err != nil {
+		t.Fatalf("ListSecurityEvents: %v", err)
+	}
+	if len(events) != 1 || events[0].Kind != models.SecurityEventUploadInfected {
+		t.Fatalf("expected an upload_infected event, got %+v", events)
+	}
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+
+	"bitriver-live/internal/storage"
+)
+
+const (
+	defaultListPerPage = 50
+	maxListPerPage     = 200
+)
+
+// pageResponse is the envelope returned by listings when the caller asks for
+// pagination.
+type pageResponse struct {
+	Items   interface{} `json:"items"`
+	Total   int         `json:"total"`
+	Page    int         `json:"page"`
+	PerPage int         `json:"perPage"`
+}
+
+// userListQuery holds the parsed q, role, sort, page, and perPage parameters
+// shared by the user and profile listings.
+type userListQuery struct {
+	Options storage.UserListOptions
+	Page    int
+	PerPage int
+}
+
+// parseUserListQuery reads the listing parameters. Unless alwaysPage is set,
+// it reports false when none are present so handlers can keep returning the
+// legacy bare array. sort takes created_at, display_name, or email, prefixed
+// with "-" for descending order.
+func parseUserListQuery(values url.Values, alwaysPage bool) (userListQuery, bool, error) {
+	paginated := alwaysPage
+	for _, key := range []string{"q", "role", "sort", "page", "perPage"} {
+		if _, ok := values[key]; ok {
+			paginated = true
+		}
+	}
+	query := userListQuery{Page: 1, PerPage: defaultListPerPage}
+	if !paginated {
+		return query, false, nil
+	}
+	query.Options.Query = strings.TrimSpace(values.Get("q"))
+	query.Options.Role = strings.ToLower(strings.TrimSpace(values.Get("role")))
+	if sortKey := strings.TrimSpace(values.Get("sort")); sortKey != "" {
+		if strings.HasPrefix(sortKey, "-") {
+			query.Options.Descending = true
+			sortKey = sortKey[1:]
+		}
+		switch sortKey {
+		case storage.UserSortCreatedAt, storage.UserSortDisplayName, storage.UserSortEmail:
+			query.Options.Sort = sortKey
+		default:
+			return userListQuery{}, true, ValidationError("sort must be created_at, display_name, or email, optionally prefixed with -")
+		}
+	}
+	page, perPage, err := parsePageParams(values)
+	if err != nil {
+		return userListQuery{}, true, err
+	}
+	query.Page, query.PerPage = page, perPage
+	query.Options.Limit = query.PerPage
+	query.Options.Offset = (query.Page - 1) * query.PerPage
+	return query, true, nil
+}
+
+// parsePageParams reads the page and perPage parameters, defaulting to the
+// first page of defaultListPerPage items.
+func parsePageParams(values url.Values) (int, int, error) {
+	page, perPage := 1, defaultListPerPage
+	if raw := strings.TrimSpace(values.Get("page")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 {
+			return 0, 0, ValidationError("page must be a positive integer")
+		}
+		page = parsed
+	}
+	if raw := strings.TrimSpace(values.Get("perPage")); raw != "" {
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 1 || parsed > maxListPerPage {
+			return 0, 0, ValidationError("perPage must be between 1 and " + strconv.Itoa(maxListPerPage))
+		}
+		perPage = parsed
+	}
+	return page, perPage, nil
+}
+
+func writePage(w http.ResponseWriter, query userListQuery, items interface{}, total int) {
+	WriteJSON(w, http.StatusOK, pageResponse{Items: items, Total: total, Page: query.Page, PerPage: query.PerPage})
+}
@@ -0,0 +1,48 @@
+package api
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+// writeUsernameError answers with the status matching a username rule that
+// err breaks. It reports false, writing nothing, for any other error.
+func writeUsernameError(w http.ResponseWriter, err error) bool {
+	switch {
+	case errors.Is(err, storage.ErrUsernameInvalid), errors.Is(err, storage.ErrUsernameReserved):
+		WriteRequestError(w, RequestError{Status: http.StatusBadRequest, CodeVal: "invalid_username", Message: err.Error(), Err: err})
+	case errors.Is(err, storage.ErrUsernameTaken):
+		WriteRequestError(w, RequestError{Status: http.StatusConflict, CodeVal: "username_taken", Message: err.Error(), Err: err})
+	case errors.Is(err, storage.ErrUsernameCooldown):
+		WriteRequestError(w, RequestError{Status: http.StatusConflict, CodeVal: "username_cooldown", Message: err.Error(), Err: err})
+	default:
+		return false
+	}
+	return true
+}
+
+// findUserByHandle resolves a username, with or without a leading "@".
+func (h *Handler) findUserByHandle(handle string) (models.User, bool) {
+	return h.Store.FindUserByUsername(strings.TrimPrefix(handle, "@"))
+}
+
+// userByHandle serves /api/users/by-handle/{username} with the user's
+// public profile view.
+func (h *Handler) userByHandle(w http.ResponseWriter, r *http.Request, handle string) {
+	if r.Method != http.MethodGet {
+		WriteMethodNotAllowed(w, r, http.MethodGet)
+		return
+	}
+	user, ok := h.findUserByHandle(handle)
+	if !ok || user.Deactivated() {
+		WriteError(w, http.StatusNotFound, fmt.Errorf("user @%s not found", strings.TrimPrefix(handle, "@")))
+		return
+	}
+	profile, _ := h.Store.GetProfile(user.ID)
+	h.writeProfileView(w, user, profile)
+}
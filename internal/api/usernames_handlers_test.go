@@ -0,0 +1,93 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+func TestUsernameSignupChangeAndLookup(t *testing.T) {
+	handler, store := newTestHandler(t)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	handler.Now = func() time.Time { return now }
+
+	signup := func(body string) *httptest.ResponseRecorder {
+		rec := httptest.NewRecorder()
+		handler.Signup(rec, httptest.NewRequest(http.MethodPost, "/api/auth/signup", strings.NewReader(body)))
+		return rec
+	}
+	rec := signup(`{"displayName":"Ada","username":"Ada_L","email":"ada@example.com","password":"first-secret"}`)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected signup to succeed, got %d: %s", rec.Code, rec.Body.String())
+	}
+	var created authResponse
+	if err := json.Unmarshal(rec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode signup: %v", err)
+	}
+	if created.User.Username != "Ada_L" {
+		t.Fatalf("expected the chosen username, got %+v", created.User)
+	}
+	rec = signup(`{"displayName":"Copy","username":"ADA_L","email":"copy@example.com","password":"first-secret"}`)
+	if rec.Code != http.StatusConflict || decodeAPIError(t, rec.Body.Bytes()).Error.Code != "username_taken" {
+		t.Fatalf("expected 409 username_taken, got %d: %s", rec.Code, rec.Body.String())
+	}
+	rec = signup(`{"displayName":"Copy","username":"settings","email":"copy@example.com","password":"first-secret"}`)
+	if rec.Code != http.StatusBadRequest || decodeAPIError(t, rec.Body.Bytes()).Error.Code != "invalid_username" {
+		t.Fatalf("expected 400 invalid_username, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	grace, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Grace Hopper", Email: "grace@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	rename := func(user models.User, username string) *httptest.ResponseRecorder {
+		req := withUser(httptest.NewRequest(http.MethodPatch, "/api/users/me", strings.NewReader(`{"username":"`+username+`"}`)), user)
+		rec := httptest.NewRecorder()
+		handler.UserByID(rec, req)
+		return rec
+	}
+	if rec := rename(grace, "Amazing_Grace"); rec.Code != http.StatusOK {
+		t.Fatalf("expected the assigned username to change, got %d: %s", rec.Code, rec.Body.String())
+	}
+	grace, _ = store.GetUser(grace.ID)
+	rec = rename(grace, "Admiral")
+	if rec.Code != http.StatusConflict || decodeAPIError(t, rec.Body.Bytes()).Error.Code != "username_cooldown" {
+		t.Fatalf("expected 409 username_cooldown, got %d: %s", rec.Code, rec.Body.String())
+	}
+	now = now.Add(storage.UsernameChangeCooldown)
+	if rec := rename(grace, "Admiral"); rec.Code != http.StatusOK {
+		t.Fatalf("expected a change after the cooldown, got %d: %s", rec.Code, rec.Body.String())
+	}
+
+	for _, path := range []string{"/api/users/by-handle/admiral", "/api/users/by-handle/@ADMIRAL", "/api/profiles/admiral", "/api/profiles/@Admiral", "/api/profiles/" + grace.ID} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		rec := httptest.NewRecorder()
+		if strings.HasPrefix(path, "/api/profiles/") {
+			handler.ProfileByID(rec, req)
+		} else {
+			handler.UserByID(rec, req)
+		}
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected 200, got %d: %s", path, rec.Code, rec.Body.String())
+		}
+		var profile profileViewResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &profile); err != nil {
+			t.Fatalf("decode profile: %v", err)
+		}
+		if profile.UserID != grace.ID || profile.Username != "Admiral" {
+			t.Fatalf("%s: expected the profile of %s, got %+v", path, grace.ID, profile)
+		}
+	}
+	req := httptest.NewRequest(http.MethodGet, "/api/users/by-handle/Amazing_Grace", nil)
+	rec = httptest.NewRecorder()
+	handler.UserByID(rec, req)
+	if rec.Code != http.StatusNotFound {
+		t.Fatalf("expected the released username to be unknown, got %d", rec.Code)
+	}
+}
@@ -0,0 +1,115 @@
+package api
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// APIVersion identifies the URL prefix a request was routed through. Handlers
+// are registered once under /api/; the router strips a version segment before
+// dispatch and records it on the request context so handlers can choose the
+// response shape.
+type APIVersion string
+
+const (
+	// APIVersionLegacy is the unversioned /api/ prefix. It keeps serving the
+	// shapes existing clients rely on, flagged as deprecated where a newer
+	// one exists.
+	APIVersionLegacy APIVersion = ""
+	// APIVersion1 is the /api/v1/ prefix.
+	APIVersion1 APIVersion = "v1"
+)
+
+const apiVersionContextKey contextKey = "apiVersion"
+
+// apiVersions lists the versions the router recognises in a path.
+var apiVersions = []APIVersion{APIVersion1}
+
+// Versioned reports whether the version came from an explicit prefix.
+func (v APIVersion) Versioned() bool {
+	return v != APIVersionLegacy
+}
+
+// String returns the version label used in logs, "legacy" for the
+// unversioned prefix.
+func (v APIVersion) String() string {
+	if v == APIVersionLegacy {
+		return "legacy"
+	}
+	return string(v)
+}
+
+// SplitAPIVersion maps a request path onto the route handlers are registered
+// under. "/api/v1/users" yields APIVersion1 and "/api/users"; every other path
+// is returned unchanged with APIVersionLegacy.
+func SplitAPIVersion(path string) (APIVersion, string) {
+	for _, version := range apiVersions {
+		prefix := "/api/" + string(version)
+		if path == prefix {
+			return version, "/api"
+		}
+		if strings.HasPrefix(path, prefix+"/") {
+			return version, "/api" + strings.TrimPrefix(path, prefix)
+		}
+	}
+	return APIVersionLegacy, path
+}
+
+// VersionedPath returns the public path of an /api/ route under version.
+func VersionedPath(version APIVersion, route string) string {
+	if !version.Versioned() || (route != "/api" && !strings.HasPrefix(route, "/api/")) {
+		return route
+	}
+	return "/api/" + string(version) + strings.TrimPrefix(route, "/api")
+}
+
+// ContextWithAPIVersion records the version prefix the router matched.
+func ContextWithAPIVersion(ctx context.Context, version APIVersion) context.Context {
+	return context.WithValue(ctx, apiVersionContextKey, version)
+}
+
+// APIVersionFromContext returns the version prefix the request was routed
+// through, APIVersionLegacy when none was recorded.
+func APIVersionFromContext(ctx context.Context) APIVersion {
+	version, _ := ctx.Value(apiVersionContextKey).(APIVersion)
+	return version
+}
+
+// requestAPIVersion is APIVersionFromContext for a request.
+func requestAPIVersion(r *http.Request) APIVersion {
+	return APIVersionFromContext(r.Context())
+}
+
+// deprecation describes a response shape kept only for legacy clients.
+type deprecation struct {
+	// Successor is the path that serves the replacement shape.
+	Successor string
+	// Sunset is when the legacy shape will be removed. Zero omits the
+	// Sunset header.
+	Sunset time.Time
+}
+
+// setDeprecationHeaders advertises that the response uses a deprecated shape
+// with a Deprecation header, a Sunset date when known, and a link to the
+// successor version.
+func setDeprecationHeaders(w http.ResponseWriter, d deprecation) {
+	w.Header().Set("Deprecation", "true")
+	if !d.Sunset.IsZero() {
+		w.Header().Set("Sunset", d.Sunset.UTC().Format(http.TimeFormat))
+	}
+	if d.Successor != "" {
+		w.Header().Add("Link", fmt.Sprintf("<%s>; rel=\"successor-version\"", d.Successor))
+	}
+}
+
+// markLegacyShape flags a response served in a legacy shape to an unversioned
+// request, pointing clients at the same route under APIVersion1.
+func (h *Handler) markLegacyShape(w http.ResponseWriter, r *http.Request) {
+	setDeprecationHeaders(w, deprecation{
+		Successor: VersionedPath(APIVersion1, r.URL.Path),
+		Sunset:    h.LegacyAPISunset,
+	})
+}
@@ -0,0 +1,96 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/storage"
+)
+
+func TestSplitAPIVersion(t *testing.T) {
+	cases := []struct {
+		path    string
+		version APIVersion
+		route   string
+	}{
+		{path: "/api/v1/users", version: APIVersion1, route: "/api/users"},
+		{path: "/api/v1/users/me/tokens", version: APIVersion1, route: "/api/users/me/tokens"},
+		{path: "/api/v1", version: APIVersion1, route: "/api"},
+		{path: "/api/users", version: APIVersionLegacy, route: "/api/users"},
+		{path: "/api/v1users", version: APIVersionLegacy, route: "/api/v1users"},
+		{path: "/api/v2/users", version: APIVersionLegacy, route: "/api/v2/users"},
+		{path: "/static/v1/app.js", version: APIVersionLegacy, route: "/static/v1/app.js"},
+	}
+	for _, tc := range cases {
+		version, route := SplitAPIVersion(tc.path)
+		if version != tc.version || route != tc.route {
+			t.Errorf("SplitAPIVersion(%q) = (%q, %q), want (%q, %q)", tc.path, version, route, tc.version, tc.route)
+		}
+		if got := VersionedPath(version, route); got != tc.path {
+			t.Errorf("VersionedPath(%q, %q) = %q, want %q", version, route, got, tc.path)
+		}
+	}
+}
+
+func TestUsersListingShapeFollowsAPIVersion(t *testing.T) {
+	handler, store := newTestHandler(t)
+	admin, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Admin", Email: "admin@example.com", Roles: []string{"admin"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	sunset := time.Date(2027, 1, 31, 0, 0, 0, 0, time.UTC)
+	handler.LegacyAPISunset = sunset
+
+	list := func(path string, version APIVersion) *httptest.ResponseRecorder {
+		t.Helper()
+		req := withUser(httptest.NewRequest(http.MethodGet, path, nil), admin)
+		req = req.WithContext(ContextWithAPIVersion(req.Context(), version))
+		rec := httptest.NewRecorder()
+		handler.Users(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s (%s): expected 200, got %d: %s", path, version, rec.Code, rec.Body.String())
+		}
+		return rec
+	}
+
+	legacy := list("/api/users", APIVersionLegacy)
+	var bare []userResponse
+	if err := json.Unmarshal(legacy.Body.Bytes(), &bare); err != nil {
+		t.Fatalf("expected a bare array under the legacy prefix: %v", err)
+	}
+	if len(bare) != 1 || bare[0].ID != admin.ID {
+		t.Fatalf("unexpected legacy listing: %+v", bare)
+	}
+	if got := legacy.Header().Get("Deprecation"); got != "true" {
+		t.Fatalf("expected Deprecation header, got %q", got)
+	}
+	if got := legacy.Header().Get("Sunset"); got != sunset.Format(http.TimeFormat) {
+		t.Fatalf("expected Sunset %q, got %q", sunset.Format(http.TimeFormat), got)
+	}
+	if got := legacy.Header().Get("Link"); got != `</api/v1/users>; rel="successor-version"` {
+		t.Fatalf("expected successor link, got %q", got)
+	}
+
+	versioned := list("/api/users", APIVersion1)
+	var page testPage[userResponse]
+	if err := json.Unmarshal(versioned.Body.Bytes(), &page); err != nil {
+		t.Fatalf("expected the page envelope under v1: %v", err)
+	}
+	if page.Total != 1 || page.Page != 1 || page.PerPage != defaultListPerPage || len(page.Items) != 1 {
+		t.Fatalf("unexpected v1 page: %+v", page)
+	}
+	if got := versioned.Header().Get("Deprecation"); got != "" {
+		t.Fatalf("expected no Deprecation header under v1, got %q", got)
+	}
+
+	paged := list("/api/users?perPage=1", APIVersionLegacy)
+	if err := json.Unmarshal(paged.Body.Bytes(), &page); err != nil || page.PerPage != 1 {
+		t.Fatalf("expected the envelope when legacy clients ask for a page: %v %+v", err, page)
+	}
+	if got := paged.Header().Get("Deprecation"); got != "" {
+		t.Fatalf("expected no Deprecation header on the envelope, got %q", got)
+	}
+}
@@ -129,7 +129,7 @@ func TestViewerContractEndpoints(t *testing.T) {
 
 	messages := []string{"first", "second", "third"}
 	for _, body := range messages {
-		if _, err := repo.CreateChatMessage(channel.ID, viewer.ID, body); err != nil {
+		if _, err := repo.CreateChatMessage(channel.ID, viewer.ID, body, ""); err != nil {
 			t.Fatalf("create chat message: %v", err)
 		}
 	}
@@ -0,0 +1,77 @@
+package auth
+
+import (
+	"context"
+	"log/slog"
+
+	"bitriver-live/internal/observability/logging"
+)
+
+// Audit actions recorded over the authentication lifecycle.
+const (
+	AuditActionSignup          = "auth.signup"
+	AuditActionLogin           = "auth.login"
+	AuditActionLoginFailure    = "auth.login_failure"
+	AuditActionLogout          = "auth.logout"
+	AuditActionSessionExpire   = "auth.session_expire"
+	AuditActionOAuthLogin      = "auth.oauth_login"
+	AuditActionPasswordSet     = "user.password_set"
+	AuditActionPasswordChange  = "user.password_change"
+	AuditActionPasswordFailure = "user.password_change_failure"
+)
+
+// Reason categories attached to failed authentication events. They name the
+// kind of failure only, never the credentials involved.
+const (
+	AuditReasonInvalidCredentials = "invalid_credentials"
+	AuditReasonAccountDeactivated = "account_deactivated"
+	AuditReasonProviderError      = "provider_error"
+)
+
+// AuditEvent is one entry in the authentication audit trail. Fields left
+// empty are omitted from the record.
+type AuditEvent struct {
+	Action string
+	// ActorID is the account that acted and UserID the account whose
+	// credentials or session changed. They differ when an administrator
+	// acts on someone else, and ActorID is empty for events the server
+	// raises itself, such as session expiry.
+	ActorID string
+	UserID  string
+	// Email is the address a failed sign-in named, recorded when no
+	// account could be identified.
+	Email          string
+	Reason         string
+	Provider       string
+	ImpersonatorID string
+	RemoteIP       string
+	UserAgent      string
+}
+
+// EmitAudit records event on logger, tagged with the request ID held in ctx.
+// Every authentication event goes through here, so a persistent audit store
+// only has to be wired in once.
+func EmitAudit(ctx context.Context, logger *slog.Logger, event AuditEvent) {
+	if logger == nil {
+		return
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	fields := []any{"action", event.Action}
+	for _, field := range []struct{ key, value string }{
+		{"actor_id", event.ActorID},
+		{"user_id", event.UserID},
+		{"email", event.Email},
+		{"reason", event.Reason},
+		{"provider", event.Provider},
+		{"impersonator_id", event.ImpersonatorID},
+		{"remote_ip", event.RemoteIP},
+		{"user_agent", event.UserAgent},
+	} {
+		if field.value != "" {
+			fields = append(fields, field.key, field.value)
+		}
+	}
+	logging.WithContext(ctx, logger).InfoContext(ctx, "audit", fields...)
+}
@@ -19,9 +19,9 @@ func NewMemorySessionStore() *MemorySessionStore {
 }
 
 // Save records the session details for the provided token.
-func (s *MemorySessionStore) Save(token, userID string, expiresAt, absoluteExpiresAt time.Time) error {
+func (s *MemorySessionStore) Save(record SessionRecord) error {
 	s.mu.Lock()
-	s.sessions[token] = SessionRecord{Token: token, UserID: userID, ExpiresAt: expiresAt, AbsoluteExpiresAt: absoluteExpiresAt}
+	s.sessions[record.Token] = record
 	s.mu.Unlock()
 	return nil
 }
@@ -42,16 +42,38 @@ func (s *MemorySessionStore) Delete(token string) error {
 	return nil
 }
 
+// DeleteUser removes every session belonging to the user or impersonating
+// someone on their behalf.
+func (s *MemorySessionStore) DeleteUser(userID string) error {
+	s.mu.Lock()
+	for token, record := range s.sessions {
+		if record.UserID == userID || record.ImpersonatorID == userID {
+			delete(s.sessions, token)
+		}
+	}
+	s.mu.Unlock()
+	return nil
+}
+
 // PurgeExpired removes any expired sessions from the store.
 func (s *MemorySessionStore) PurgeExpired(now time.Time) error {
+	_, err := s.PurgeExpiredSessions(now)
+	return err
+}
+
+// PurgeExpiredSessions removes any expired sessions from the store and
+// returns them.
+func (s *MemorySessionStore) PurgeExpiredSessions(now time.Time) ([]SessionRecord, error) {
+	var expired []SessionRecord
 	s.mu.Lock()
 	for token, record := range s.sessions {
 		if now.After(record.ExpiresAt) || (!record.AbsoluteExpiresAt.IsZero() && now.After(record.AbsoluteExpiresAt)) {
 			delete(s.sessions, token)
+			expired = append(expired, record)
 		}
 	}
 	s.mu.Unlock()
-	return nil
+	return expired, nil
 }
 
 // Ping always reports success for the in-memory session store.
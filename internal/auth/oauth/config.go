@@ -22,6 +22,9 @@ type ProviderConfig struct {
 	Scopes       []string          `json:"scopes"`
 	AuthParams   map[string]string `json:"authParams"`
 	Profile      ProfileMapping    `json:"profile"`
+	// DisablePKCE skips the PKCE challenge for providers that reject it.
+	// PKCE is sent by default.
+	DisablePKCE bool `json:"disablePKCE"`
 }
 
 // ProfileMapping defines how to map fields from the provider's userinfo response.
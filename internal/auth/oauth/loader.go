@@ -17,6 +17,9 @@ type LoadInput struct {
 	ClientSecrets map[string]string
 	// RedirectURLs holds flag-provided per-provider redirect URLs.
 	RedirectURLs map[string]string
+	// ReturnOrigins lists extra origins flows may return to, on top of
+	// BITRIVER_LIVE_OAUTH_RETURN_ORIGINS.
+	ReturnOrigins []string
 	// LookupEnv overrides environment lookup for testing.
 	LookupEnv func(string) string
 }
@@ -57,7 +60,9 @@ func LoadFromFlagsAndEnv(input LoadInput) ([]ProviderConfig, Service, error) {
 		return nil, nil, nil
 	}
 
-	manager, err := NewManager(providers)
+	origins := append([]string(nil), input.ReturnOrigins...)
+	origins = append(origins, strings.Split(lookupEnv("BITRIVER_LIVE_OAUTH_RETURN_ORIGINS"), ",")...)
+	manager, err := NewManager(providers, WithReturnOrigins(origins))
 	if err != nil {
 		return nil, nil, fmt.Errorf("configure oauth: %w", err)
 	}
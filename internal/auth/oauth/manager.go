@@ -24,9 +24,9 @@ var ErrStateInvalid = errors.New("oauth state invalid or expired")
 // OAuth 2.0 authorisation code flow.
 type Service interface {
 	Providers() []ProviderInfo
-	Begin(provider, returnTo string) (BeginResult, error)
-	Complete(provider, state, code string) (Completion, error)
-	Cancel(state string) (string, error)
+	Begin(provider, returnTo string, binding Binding) (BeginResult, error)
+	Complete(provider, state, code string, binding Binding) (Completion, error)
+	Cancel(state string, binding Binding) (string, error)
 }
 
 // ProviderInfo is a lightweight description of a configured provider.
@@ -62,6 +62,10 @@ type Manager struct {
 	state     StateStore
 	client    *http.Client
 	stateTTL  time.Duration
+	// returnOrigins holds the normalised origins, besides this site's own
+	// paths, that a flow may return to.
+	returnOrigins map[string]struct{}
+	originInputs  []string
 }
 
 type provider struct {
@@ -98,19 +102,39 @@ func WithStateTTL(ttl time.Duration) Option {
 	}
 }
 
+// WithReturnOrigins allows flows to return to absolute URLs on the listed
+// origins, such as "https://app.example.com". Paths on this site are always
+// allowed.
+func WithReturnOrigins(origins []string) Option {
+	return func(m *Manager) {
+		m.originInputs = append(m.originInputs, origins...)
+	}
+}
+
 // NewManager constructs an OAuth manager for the provided configuration.
 func NewManager(configs []ProviderConfig, opts ...Option) (*Manager, error) {
 	mgr := &Manager{
-		providers: make(map[string]provider),
-		state:     NewMemoryStateStore(),
-		client:    &http.Client{Timeout: 10 * time.Second},
-		stateTTL:  10 * time.Minute,
+		providers:     make(map[string]provider),
+		state:         NewMemoryStateStore(),
+		client:        &http.Client{Timeout: 10 * time.Second},
+		stateTTL:      10 * time.Minute,
+		returnOrigins: make(map[string]struct{}),
 	}
 	for _, opt := range opts {
 		if opt != nil {
 			opt(mgr)
 		}
 	}
+	for _, raw := range mgr.originInputs {
+		if strings.TrimSpace(raw) == "" {
+			continue
+		}
+		origin, err := normalizeOrigin(raw)
+		if err != nil {
+			return nil, err
+		}
+		mgr.returnOrigins[origin] = struct{}{}
+	}
 	for _, cfg := range configs {
 		if err := cfg.Validate(); err != nil {
 			return nil, err
@@ -143,20 +167,33 @@ func sortProviders(items []ProviderInfo) {
 	})
 }
 
-// Begin initialises an OAuth flow for the selected provider.
-func (m *Manager) Begin(name, returnTo string) (BeginResult, error) {
+// Begin initialises an OAuth flow for the selected provider. returnTo must
+// pass ValidateReturnTo, and the state is bound to binding so only the same
+// client can complete or cancel the flow.
+func (m *Manager) Begin(name, returnTo string, binding Binding) (BeginResult, error) {
 	provider, ok := m.providers[strings.ToLower(strings.TrimSpace(name))]
 	if !ok {
 		return BeginResult{}, ErrProviderNotConfigured
 	}
+	returnTo, err := m.ValidateReturnTo(returnTo)
+	if err != nil {
+		return BeginResult{}, err
+	}
 	state, err := GenerateState()
 	if err != nil {
 		return BeginResult{}, err
 	}
-	if err := m.state.Put(state, StateData{Provider: provider.config.Name, ReturnTo: returnTo}, m.stateTTL); err != nil {
+	data := StateData{Provider: provider.config.Name, ReturnTo: returnTo, Binding: binding}
+	if !provider.config.DisablePKCE {
+		data.CodeVerifier, err = GenerateCodeVerifier()
+		if err != nil {
+			return BeginResult{}, err
+		}
+	}
+	if err := m.state.Put(state, data, m.stateTTL); err != nil {
 		return BeginResult{}, err
 	}
-	authURL, err := buildAuthorizeURL(provider.config, state)
+	authURL, err := buildAuthorizeURL(provider.config, state, data.CodeVerifier)
 	if err != nil {
 		return BeginResult{}, err
 	}
@@ -164,7 +201,9 @@ func (m *Manager) Begin(name, returnTo string) (BeginResult, error) {
 }
 
 // Complete exchanges the authorisation code and returns the provider profile.
-func (m *Manager) Complete(name, state, code string) (Completion, error) {
+// The state is consumed whatever the outcome, and the returned ReturnTo has
+// been validated again so it is always safe to redirect to.
+func (m *Manager) Complete(name, state, code string, binding Binding) (Completion, error) {
 	provider, ok := m.providers[strings.ToLower(strings.TrimSpace(name))]
 	if !ok {
 		return Completion{}, ErrProviderNotConfigured
@@ -177,11 +216,14 @@ func (m *Manager) Complete(name, state, code string) (Completion, error) {
 	if !ok {
 		return Completion{}, ErrStateInvalid
 	}
+	if !data.Binding.matches(binding) {
+		return Completion{}, ErrStateInvalid
+	}
+	completion := Completion{ReturnTo: m.safeReturnTo(data.ReturnTo)}
 	if !strings.EqualFold(data.Provider, provider.config.Name) {
-		return Completion{ReturnTo: data.ReturnTo}, ErrStateInvalid
+		return completion, ErrStateInvalid
 	}
-	completion := Completion{ReturnTo: data.ReturnTo}
-	token, err := m.exchangeCode(provider.config, code)
+	token, err := m.exchangeCode(provider.config, code, data.CodeVerifier)
 	if err != nil {
 		return completion, err
 	}
@@ -193,20 +235,21 @@ func (m *Manager) Complete(name, state, code string) (Completion, error) {
 	return completion, nil
 }
 
-// Cancel invalidates the provided state token and returns the saved return URL.
-func (m *Manager) Cancel(state string) (string, error) {
+// Cancel invalidates the provided state token and returns the saved return
+// URL, validated again before it is used for a redirect.
+func (m *Manager) Cancel(state string, binding Binding) (string, error) {
 	state = strings.TrimSpace(state)
 	if state == "" {
 		return "", ErrStateInvalid
 	}
 	data, ok := m.state.Take(state)
-	if !ok {
+	if !ok || !data.Binding.matches(binding) {
 		return "", ErrStateInvalid
 	}
-	return data.ReturnTo, nil
+	return m.safeReturnTo(data.ReturnTo), nil
 }
 
-func buildAuthorizeURL(cfg ProviderConfig, state string) (string, error) {
+func buildAuthorizeURL(cfg ProviderConfig, state, verifier string) (string, error) {
 	parsed, err := url.Parse(cfg.AuthorizeURL)
 	if err != nil {
 		return "", fmt.Errorf("parse authorize url: %w", err)
@@ -222,6 +265,10 @@ func buildAuthorizeURL(cfg ProviderConfig, state string) (string, error) {
 	for key, value := range cfg.AuthParams {
 		query.Set(key, value)
 	}
+	if verifier != "" {
+		query.Set("code_challenge", CodeChallenge(verifier))
+		query.Set("code_challenge_method", codeChallengeMethod)
+	}
 	parsed.RawQuery = query.Encode()
 	return parsed.String(), nil
 }
@@ -233,7 +280,7 @@ type tokenResponse struct {
 	Raw         map[string]any
 }
 
-func (m *Manager) exchangeCode(cfg ProviderConfig, code string) (tokenResponse, error) {
+func (m *Manager) exchangeCode(cfg ProviderConfig, code, verifier string) (tokenResponse, error) {
 	code = strings.TrimSpace(code)
 	if code == "" {
 		return tokenResponse{}, fmt.Errorf("authorization code is required")
@@ -244,6 +291,9 @@ func (m *Manager) exchangeCode(cfg ProviderConfig, code string) (tokenResponse,
 	payload.Set("redirect_uri", cfg.RedirectURL)
 	payload.Set("client_id", cfg.ClientID)
 	payload.Set("client_secret", cfg.ClientSecret)
+	if verifier != "" {
+		payload.Set("code_verifier", verifier)
+	}
 
 	request, err := http.NewRequest(http.MethodPost, cfg.TokenURL, strings.NewReader(payload.Encode()))
 	if err != nil {
@@ -8,6 +8,7 @@ import (
 	"net/url"
 	"strings"
 	"testing"
+	"time"
 )
 
 func TestManagerBeginAndComplete(t *testing.T) {
@@ -64,7 +65,7 @@ func TestManagerBeginAndComplete(t *testing.T) {
 		t.Fatalf("NewManager returned error: %v", err)
 	}
 
-	begin, err := mgr.Begin("test", "/dashboard")
+	begin, err := mgr.Begin("test", "/dashboard", Binding{})
 	if err != nil {
 		t.Fatalf("Begin returned error: %v", err)
 	}
@@ -82,7 +83,7 @@ func TestManagerBeginAndComplete(t *testing.T) {
 		t.Fatalf("expected client id in authorize url")
 	}
 
-	completion, err := mgr.Complete("test", begin.State, "code-xyz")
+	completion, err := mgr.Complete("test", begin.State, "code-xyz", Binding{})
 	if err != nil {
 		t.Fatalf("Complete returned error: %v", err)
 	}
@@ -114,22 +115,232 @@ func TestManagerCancel(t *testing.T) {
 	if err != nil {
 		t.Fatalf("NewManager returned error: %v", err)
 	}
-	begin, err := mgr.Begin("example", "/viewer")
+	begin, err := mgr.Begin("example", "/viewer", Binding{})
 	if err != nil {
 		t.Fatalf("Begin returned error: %v", err)
 	}
-	redirected, err := mgr.Cancel(begin.State)
+	redirected, err := mgr.Cancel(begin.State, Binding{})
 	if err != nil {
 		t.Fatalf("Cancel returned error: %v", err)
 	}
 	if redirected != "/viewer" {
 		t.Fatalf("expected redirect /viewer, got %q", redirected)
 	}
-	if _, err := mgr.Cancel(begin.State); !errors.Is(err, ErrStateInvalid) {
+	if _, err := mgr.Cancel(begin.State, Binding{}); !errors.Is(err, ErrStateInvalid) {
 		t.Fatalf("expected ErrStateInvalid on reused state, got %v", err)
 	}
 }
 
+// fakeProvider serves token and userinfo endpoints and records every token
+// exchange form it receives.
+func fakeProvider(t *testing.T) (*httptest.Server, *[]url.Values) {
+	t.Helper()
+	var exchanges []url.Values
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/token":
+			if err := r.ParseForm(); err != nil {
+				t.Errorf("parse token form: %v", err)
+			}
+			exchanges = append(exchanges, r.PostForm)
+			_ = json.NewEncoder(w).Encode(map[string]string{"access_token": "token-123", "token_type": "Bearer"})
+		case "/userinfo":
+			_ = json.NewEncoder(w).Encode(map[string]any{"id": "user-1", "email": "viewer@example.com", "name": "Viewer"})
+		default:
+			t.Errorf("unexpected request path %s", r.URL.Path)
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &exchanges
+}
+
+func fakeProviderConfig(server *httptest.Server) ProviderConfig {
+	cfg := exampleProviderConfig()
+	cfg.TokenURL = server.URL + "/token"
+	cfg.UserInfoURL = server.URL + "/userinfo"
+	return cfg
+}
+
+func TestManagerSendsPKCEVerifierOnExchange(t *testing.T) {
+	server, exchanges := fakeProvider(t)
+	mgr, err := NewManager([]ProviderConfig{fakeProviderConfig(server)})
+	if err != nil {
+		t.Fatalf("NewManager returned error: %v", err)
+	}
+	begin, err := mgr.Begin("example", "/viewer", Binding{})
+	if err != nil {
+		t.Fatalf("Begin returned error: %v", err)
+	}
+	authorize, err := url.Parse(begin.URL)
+	if err != nil {
+		t.Fatalf("parse authorize url: %v", err)
+	}
+	challenge := authorize.Query().Get("code_challenge")
+	if challenge == "" || authorize.Query().Get("code_challenge_method") != "S256" {
+		t.Fatalf("expected an S256 challenge in %s", begin.URL)
+	}
+	if _, err := mgr.Complete("example", begin.State, "code-1", Binding{}); err != nil {
+		t.Fatalf("Complete returned error: %v", err)
+	}
+	if len(*exchanges) != 1 {
+		t.Fatalf("expected one token exchange, got %d", len(*exchanges))
+	}
+	verifier := (*exchanges)[0].Get("code_verifier")
+	if verifier == "" || CodeChallenge(verifier) != challenge {
+		t.Fatalf("expected the verifier for challenge %q, got %q", challenge, verifier)
+	}
+}
+
+func TestManagerSkipsPKCEWhenDisabled(t *testing.T) {
+	server, exchanges := fakeProvider(t)
+	cfg := fakeProviderConfig(server)
+	cfg.DisablePKCE = true
+	mgr, err := NewManager([]ProviderConfig{cfg})
+	if err != nil {
+		t.Fatalf("NewManager returned error: %v", err)
+	}
+	begin, err := mgr.Begin("example", "/viewer", Binding{})
+	if err != nil {
+		t.Fatalf("Begin returned error: %v", err)
+	}
+	if strings.Contains(begin.URL, "code_challenge") {
+		t.Fatalf("expected no challenge for a provider without PKCE, got %s", begin.URL)
+	}
+	if _, err := mgr.Complete("example", begin.State, "code-1", Binding{}); err != nil {
+		t.Fatalf("Complete returned error: %v", err)
+	}
+	if _, ok := (*exchanges)[0]["code_verifier"]; ok {
+		t.Fatal("expected no code_verifier in the exchange")
+	}
+}
+
+func TestManagerValidatesReturnToAtBegin(t *testing.T) {
+	mgr, err := NewManager([]ProviderConfig{exampleProviderConfig()}, WithReturnOrigins([]string{"https://App.example.com/"}))
+	if err != nil {
+		t.Fatalf("NewManager returned error: %v", err)
+	}
+	rejected := []string{
+		"https://evil.example.com/",
+		"//evil.example.com/path",
+		"/\\evil.example.com",
+		"javascript:alert(1)",
+		"https://app.example.com@evil.example.com/",
+		"https://user@app.example.com/",
+		"http://app.example.com/",
+		"dashboard",
+	}
+	for _, target := range rejected {
+		if _, err := mgr.Begin("example", target, Binding{}); !errors.Is(err, ErrReturnToInvalid) {
+			t.Fatalf("%q: expected ErrReturnToInvalid, got %v", target, err)
+		}
+	}
+	accepted := map[string]string{
+		"":                                 "/",
+		"/control?tab=vod":                 "/control?tab=vod",
+		"https://app.example.com/settings": "https://app.example.com/settings",
+	}
+	for target, expected := range accepted {
+		begin, err := mgr.Begin("example", target, Binding{})
+		if err != nil {
+			t.Fatalf("%q: Begin returned error: %v", target, err)
+		}
+		returned, err := mgr.Cancel(begin.State, Binding{})
+		if err != nil || returned != expected {
+			t.Fatalf("%q: expected %q, got %q (err %v)", target, expected, returned, err)
+		}
+	}
+	if _, err := NewManager(nil, WithReturnOrigins([]string{"app.example.com"})); err == nil {
+		t.Fatal("expected an origin without a scheme to be rejected")
+	}
+}
+
+func TestManagerRevalidatesStoredReturnTo(t *testing.T) {
+	server, _ := fakeProvider(t)
+	store := NewMemoryStateStore()
+	mgr, err := NewManager([]ProviderConfig{fakeProviderConfig(server)}, WithStateStore(store))
+	if err != nil {
+		t.Fatalf("NewManager returned error: %v", err)
+	}
+	// A shared state store may hold entries written by a differently
+	// configured instance, so stored values are never trusted.
+	for _, state := range []string{"complete-state", "cancel-state"} {
+		if err := store.Put(state, StateData{Provider: "example", ReturnTo: "https://evil.example.com/"}, time.Minute); err != nil {
+			t.Fatalf("Put: %v", err)
+		}
+	}
+	completion, err := mgr.Complete("example", "complete-state", "code-1", Binding{})
+	if err != nil {
+		t.Fatalf("Complete returned error: %v", err)
+	}
+	if completion.ReturnTo != "/" {
+		t.Fatalf("expected Complete to fall back to /, got %q", completion.ReturnTo)
+	}
+	returned, err := mgr.Cancel("cancel-state", Binding{})
+	if err != nil || returned != "/" {
+		t.Fatalf("expected Cancel to fall back to /, got %q (err %v)", returned, err)
+	}
+}
+
+func TestManagerStateIsSingleUseAndBound(t *testing.T) {
+	server, exchanges := fakeProvider(t)
+	mgr, err := NewManager([]ProviderConfig{fakeProviderConfig(server)})
+	if err != nil {
+		t.Fatalf("NewManager returned error: %v", err)
+	}
+	binding := Binding{Session: "nonce-1", IP: "203.0.113.7"}
+	begin, err := mgr.Begin("example", "/viewer", binding)
+	if err != nil {
+		t.Fatalf("Begin returned error: %v", err)
+	}
+	if _, err := mgr.Complete("example", begin.State, "code-1", binding); err != nil {
+		t.Fatalf("Complete returned error: %v", err)
+	}
+	if _, err := mgr.Complete("example", begin.State, "code-1", binding); !errors.Is(err, ErrStateInvalid) {
+		t.Fatalf("expected ErrStateInvalid on reuse, got %v", err)
+	}
+
+	for _, other := range []Binding{{Session: "nonce-2", IP: "203.0.113.7"}, {Session: "nonce-1", IP: "198.51.100.1"}, {}} {
+		begin, err := mgr.Begin("example", "/viewer", binding)
+		if err != nil {
+			t.Fatalf("Begin returned error: %v", err)
+		}
+		if _, err := mgr.Complete("example", begin.State, "code-1", other); !errors.Is(err, ErrStateInvalid) {
+			t.Fatalf("expected ErrStateInvalid for %+v, got %v", other, err)
+		}
+		if _, err := mgr.Cancel(begin.State, binding); !errors.Is(err, ErrStateInvalid) {
+			t.Fatalf("expected the mismatched attempt to consume the state, got %v", err)
+		}
+	}
+	if len(*exchanges) != 1 {
+		t.Fatalf("expected no token exchange for rejected states, got %d", len(*exchanges))
+	}
+}
+
+func TestManagerStateExpires(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	store := newMemoryStateStore(func() time.Time { return now })
+	mgr, err := NewManager([]ProviderConfig{exampleProviderConfig()}, WithStateStore(store), WithStateTTL(5*time.Minute))
+	if err != nil {
+		t.Fatalf("NewManager returned error: %v", err)
+	}
+	fresh, err := mgr.Begin("example", "/viewer", Binding{})
+	if err != nil {
+		t.Fatalf("Begin returned error: %v", err)
+	}
+	stale, err := mgr.Begin("example", "/viewer", Binding{})
+	if err != nil {
+		t.Fatalf("Begin returned error: %v", err)
+	}
+	now = now.Add(4 * time.Minute)
+	if _, err := mgr.Cancel(fresh.State, Binding{}); err != nil {
+		t.Fatalf("expected a state within its TTL to be accepted, got %v", err)
+	}
+	now = now.Add(2 * time.Minute)
+	if _, err := mgr.Complete("example", stale.State, "code-1", Binding{}); !errors.Is(err, ErrStateInvalid) {
+		t.Fatalf("expected ErrStateInvalid after the TTL, got %v", err)
+	}
+}
+
 func exampleProviderConfig() ProviderConfig {
 	return ProviderConfig{
 		Name:         "example",
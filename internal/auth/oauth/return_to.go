@@ -0,0 +1,74 @@
+package oauth
+
+import (
+	"errors"
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// ErrReturnToInvalid is returned when a return URL is neither a path on this
+// site nor on an allowed origin.
+var ErrReturnToInvalid = errors.New("oauth return url not allowed")
+
+// normalizeOrigin reduces an origin such as "https://app.example.com/" to its
+// lower-cased scheme and host.
+func normalizeOrigin(raw string) (string, error) {
+	parsed, err := url.Parse(strings.TrimSpace(raw))
+	if err != nil {
+		return "", fmt.Errorf("parse return origin %q: %w", raw, err)
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return "", fmt.Errorf("return origin %q must use http or https", raw)
+	}
+	if parsed.Host == "" || parsed.User != nil || (parsed.Path != "" && parsed.Path != "/") || parsed.RawQuery != "" || parsed.Fragment != "" {
+		return "", fmt.Errorf("return origin %q must be a scheme and host only", raw)
+	}
+	return strings.ToLower(parsed.Scheme + "://" + parsed.Host), nil
+}
+
+// ValidateReturnTo checks where a finished flow may send the browser. An
+// empty value becomes "/", paths on this site are kept as they are, and
+// absolute URLs are only accepted on the configured return origins.
+// Anything else, including protocol-relative and backslash tricks, returns
+// ErrReturnToInvalid.
+func (m *Manager) ValidateReturnTo(raw string) (string, error) {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" {
+		return "/", nil
+	}
+	if strings.ContainsAny(trimmed, "\\\x00\r\n\t") {
+		return "", ErrReturnToInvalid
+	}
+	parsed, err := url.Parse(trimmed)
+	if err != nil {
+		return "", ErrReturnToInvalid
+	}
+	if parsed.Scheme == "" && parsed.Host == "" {
+		if !strings.HasPrefix(trimmed, "/") || strings.HasPrefix(trimmed, "//") {
+			return "", ErrReturnToInvalid
+		}
+		return trimmed, nil
+	}
+	if parsed.Scheme != "http" && parsed.Scheme != "https" {
+		return "", ErrReturnToInvalid
+	}
+	if parsed.User != nil {
+		return "", ErrReturnToInvalid
+	}
+	origin := strings.ToLower(parsed.Scheme + "://" + parsed.Host)
+	if _, ok := m.returnOrigins[origin]; !ok {
+		return "", ErrReturnToInvalid
+	}
+	return parsed.String(), nil
+}
+
+// safeReturnTo re-validates a stored return URL before it is handed back
+// for a redirect, falling back to "/" when it no longer passes.
+func (m *Manager) safeReturnTo(stored string) string {
+	validated, err := m.ValidateReturnTo(stored)
+	if err != nil {
+		return "/"
+	}
+	return validated
+}
@@ -2,6 +2,7 @@ package oauth
 
 import (
 	"crypto/rand"
+	"crypto/subtle"
 	"encoding/hex"
 	"fmt"
 	"sync"
@@ -12,10 +13,38 @@ import (
 type StateData struct {
 	Provider string
 	ReturnTo string
-	Expires  time.Time
+	// CodeVerifier is the PKCE verifier sent with the token exchange. It is
+	// empty for providers configured with DisablePKCE.
+	CodeVerifier string
+	Binding      Binding
+	Expires      time.Time
 }
 
-// StateStore tracks OAuth state parameters until they are redeemed.
+// Binding ties a state value to the client that started the flow. Empty
+// fields are not checked, so callers bind whatever they have available.
+type Binding struct {
+	// Session identifies the browser, such as a nonce cookie set when the
+	// flow began.
+	Session string
+	// IP is the client address that started the flow.
+	IP string
+}
+
+// matches reports whether presented comes from the client b was recorded
+// for.
+func (b Binding) matches(presented Binding) bool {
+	return bindingFieldMatches(b.Session, presented.Session) && bindingFieldMatches(b.IP, presented.IP)
+}
+
+func bindingFieldMatches(recorded, presented string) bool {
+	if recorded == "" {
+		return true
+	}
+	return subtle.ConstantTimeCompare([]byte(recorded), []byte(presented)) == 1
+}
+
+// StateStore tracks OAuth state parameters until they are redeemed. Take
+// must remove the entry so each state is used at most once.
 type StateStore interface {
 	Put(state string, data StateData, ttl time.Duration) error
 	Take(state string) (StateData, bool)
@@ -25,11 +54,16 @@ type StateStore interface {
 type memoryStateStore struct {
 	mu    sync.Mutex
 	items map[string]StateData
+	now   func() time.Time
 }
 
 // NewMemoryStateStore constructs an in-memory store for state parameters.
 func NewMemoryStateStore() StateStore {
-	return &memoryStateStore{items: make(map[string]StateData)}
+	return newMemoryStateStore(time.Now)
+}
+
+func newMemoryStateStore(now func() time.Time) *memoryStateStore {
+	return &memoryStateStore{items: make(map[string]StateData), now: now}
 }
 
 func (s *memoryStateStore) Put(state string, data StateData, ttl time.Duration) error {
@@ -41,7 +75,7 @@ func (s *memoryStateStore) Put(state string, data StateData, ttl time.Duration)
 	}
 	s.mu.Lock()
 	defer s.mu.Unlock()
-	data.Expires = time.Now().Add(ttl)
+	data.Expires = s.now().Add(ttl)
 	s.items[state] = data
 	s.pruneLocked()
 	return nil
@@ -58,14 +92,14 @@ func (s *memoryStateStore) Take(state string) (StateData, bool) {
 	if !ok {
 		return StateData{}, false
 	}
-	if !data.Expires.IsZero() && time.Now().After(data.Expires) {
+	if !data.Expires.IsZero() && s.now().After(data.Expires) {
 		return StateData{}, false
 	}
 	return data, true
 }
 
 func (s *memoryStateStore) pruneLocked() {
-	now := time.Now()
+	now := s.now()
 	for key, item := range s.items {
 		if !item.Expires.IsZero() && now.After(item.Expires) {
 			delete(s.items, key)
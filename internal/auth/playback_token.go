@@ -0,0 +1,150 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+)
+
+// AnonymousPlaybackSubject is the token subject issued to viewers who watch a
+// restricted channel through its preview allowance rather than as a member.
+const AnonymousPlaybackSubject = "anon"
+
+// DefaultPlaybackTokenTTL bounds how long a signed manifest URL stays valid.
+// Players refresh the playback endpoint well within this window.
+const DefaultPlaybackTokenTTL = 10 * time.Minute
+
+var (
+	// ErrPlaybackTokenInvalid reports a malformed token or one whose signature
+	// matches none of the configured keys.
+	ErrPlaybackTokenInvalid = errors.New("invalid playback token")
+	// ErrPlaybackTokenExpired reports a correctly signed token past its expiry.
+	ErrPlaybackTokenExpired = errors.New("playback token expired")
+)
+
+// PlaybackClaims are the facts a playback token vouches for.
+type PlaybackClaims struct {
+	ChannelID string
+	Subject   string
+	ExpiresAt time.Time
+}
+
+type playbackPayload struct {
+	ChannelID string `json:"c"`
+	Subject   string `json:"s"`
+	ExpiresAt int64  `json:"e"`
+}
+
+// PlaybackSignerConfig configures a PlaybackSigner. Key signs new tokens;
+// PreviousKeys still verify tokens issued before a rotation so viewers keep
+// playing until those tokens expire.
+type PlaybackSignerConfig struct {
+	Key          string
+	PreviousKeys []string
+	TTL          time.Duration
+	// Now returns the current time. Nil falls back to time.Now.
+	Now func() time.Time
+}
+
+// PlaybackSigner issues and verifies HMAC-SHA256 playback tokens.
+type PlaybackSigner struct {
+	keys [][]byte
+	ttl  time.Duration
+	now  func() time.Time
+}
+
+// NewPlaybackSigner validates cfg and returns a signer. TTL defaults to
+// DefaultPlaybackTokenTTL.
+func NewPlaybackSigner(cfg PlaybackSignerConfig) (*PlaybackSigner, error) {
+	key := strings.TrimSpace(cfg.Key)
+	if key == "" {
+		return nil, errors.New("playback signing key is required")
+	}
+	keys := [][]byte{[]byte(key)}
+	for _, previous := range cfg.PreviousKeys {
+		if trimmed := strings.TrimSpace(previous); trimmed != "" && trimmed != key {
+			keys = append(keys, []byte(trimmed))
+		}
+	}
+	if cfg.TTL < 0 {
+		return nil, fmt.Errorf("playback token ttl must be positive, got %s", cfg.TTL)
+	}
+	ttl := cfg.TTL
+	if ttl == 0 {
+		ttl = DefaultPlaybackTokenTTL
+	}
+	now := cfg.Now
+	if now == nil {
+		now = time.Now
+	}
+	return &PlaybackSigner{keys: keys, ttl: ttl, now: now}, nil
+}
+
+// Issue signs a token granting subject playback of channelID until the
+// returned expiry.
+func (s *PlaybackSigner) Issue(channelID, subject string) (string, time.Time, error) {
+	channelID = strings.TrimSpace(channelID)
+	subject = strings.TrimSpace(subject)
+	if channelID == "" || subject == "" {
+		return "", time.Time{}, errors.New("playback token requires a channel and subject")
+	}
+	expires := s.now().Add(s.ttl).UTC().Truncate(time.Second)
+	payload, err := json.Marshal(playbackPayload{ChannelID: channelID, Subject: subject, ExpiresAt: expires.Unix()})
+	if err != nil {
+		return "", time.Time{}, fmt.Errorf("encode playback token: %w", err)
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	signature := base64.RawURLEncoding.EncodeToString(signPlayback(s.keys[0], encoded))
+	return encoded + "." + signature, expires, nil
+}
+
+// Verify checks token against the current and previous keys and returns its
+// claims. Signatures are compared in constant time.
+func (s *PlaybackSigner) Verify(token string) (PlaybackClaims, error) {
+	encoded, rawSignature, ok := strings.Cut(strings.TrimSpace(token), ".")
+	if !ok || encoded == "" || rawSignature == "" {
+		return PlaybackClaims{}, ErrPlaybackTokenInvalid
+	}
+	signature, err := base64.RawURLEncoding.DecodeString(rawSignature)
+	if err != nil {
+		return PlaybackClaims{}, ErrPlaybackTokenInvalid
+	}
+	valid := false
+	for _, key := range s.keys {
+		if hmac.Equal(signature, signPlayback(key, encoded)) {
+			valid = true
+			break
+		}
+	}
+	if !valid {
+		return PlaybackClaims{}, ErrPlaybackTokenInvalid
+	}
+	raw, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return PlaybackClaims{}, ErrPlaybackTokenInvalid
+	}
+	var payload playbackPayload
+	if err := json.Unmarshal(raw, &payload); err != nil || payload.ChannelID == "" || payload.Subject == "" {
+		return PlaybackClaims{}, ErrPlaybackTokenInvalid
+	}
+	claims := PlaybackClaims{
+		ChannelID: payload.ChannelID,
+		Subject:   payload.Subject,
+		ExpiresAt: time.Unix(payload.ExpiresAt, 0).UTC(),
+	}
+	if !s.now().Before(claims.ExpiresAt) {
+		return claims, ErrPlaybackTokenExpired
+	}
+	return claims, nil
+}
+
+func signPlayback(key []byte, payload string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
@@ -0,0 +1,116 @@
+package auth
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+func newTestPlaybackSigner(t *testing.T, cfg PlaybackSignerConfig) *PlaybackSigner {
+	t.Helper()
+	signer, err := NewPlaybackSigner(cfg)
+	if err != nil {
+		t.Fatalf("NewPlaybackSigner returned error: %v", err)
+	}
+	return signer
+}
+
+func TestPlaybackTokenRoundTrip(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	signer := newTestPlaybackSigner(t, PlaybackSignerConfig{Key: "secret", TTL: time.Minute, Now: func() time.Time { return now }})
+
+	token, expires, err := signer.Issue("channel-1", "user-1")
+	if err != nil {
+		t.Fatalf("Issue returned error: %v", err)
+	}
+	if !expires.Equal(now.Add(time.Minute)) {
+		t.Fatalf("expected expiry %v, got %v", now.Add(time.Minute), expires)
+	}
+	claims, err := signer.Verify(token)
+	if err != nil {
+		t.Fatalf("Verify returned error: %v", err)
+	}
+	if claims.ChannelID != "channel-1" || claims.Subject != "user-1" || !claims.ExpiresAt.Equal(expires) {
+		t.Fatalf("unexpected claims %+v", claims)
+	}
+}
+
+func TestPlaybackTokenRejectsExpiryAndTampering(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	clock := now
+	signer := newTestPlaybackSigner(t, PlaybackSignerConfig{Key: "secret", TTL: time.Minute, Now: func() time.Time { return clock }})
+	token, _, err := signer.Issue("channel-1", AnonymousPlaybackSubject)
+	if err != nil {
+		t.Fatalf("Issue returned error: %v", err)
+	}
+
+	payload, signature, _ := strings.Cut(token, ".")
+	other, _, err := signer.Issue("channel-2", AnonymousPlaybackSubject)
+	if err != nil {
+		t.Fatalf("Issue returned error: %v", err)
+	}
+	otherPayload, _, _ := strings.Cut(other, ".")
+	flipped := "A" + signature[1:]
+	if signature[0] == 'A' {
+		flipped = "B" + signature[1:]
+	}
+	forged := newTestPlaybackSigner(t, PlaybackSignerConfig{Key: "guess", Now: func() time.Time { return clock }})
+	forgedToken, _, err := forged.Issue("channel-1", AnonymousPlaybackSubject)
+	if err != nil {
+		t.Fatalf("Issue returned error: %v", err)
+	}
+
+	for name, candidate := range map[string]string{
+		"empty":             "",
+		"missing signature": payload,
+		"swapped payload":   otherPayload + "." + signature,
+		"flipped signature": payload + "." + flipped,
+		"wrong key":         forgedToken,
+	} {
+		if _, err := signer.Verify(candidate); !errors.Is(err, ErrPlaybackTokenInvalid) {
+			t.Fatalf("%s: expected ErrPlaybackTokenInvalid, got %v", name, err)
+		}
+	}
+
+	clock = now.Add(time.Minute)
+	if _, err := signer.Verify(token); !errors.Is(err, ErrPlaybackTokenExpired) {
+		t.Fatalf("expected ErrPlaybackTokenExpired, got %v", err)
+	}
+}
+
+func TestPlaybackTokenKeyRotationOverlap(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	clock := func() time.Time { return now }
+	before := newTestPlaybackSigner(t, PlaybackSignerConfig{Key: "old", Now: clock})
+	oldToken, _, err := before.Issue("channel-1", "user-1")
+	if err != nil {
+		t.Fatalf("Issue returned error: %v", err)
+	}
+
+	during := newTestPlaybackSigner(t, PlaybackSignerConfig{Key: "new", PreviousKeys: []string{"old"}, Now: clock})
+	if _, err := during.Verify(oldToken); err != nil {
+		t.Fatalf("expected token signed with previous key to verify, got %v", err)
+	}
+	newToken, _, err := during.Issue("channel-1", "user-1")
+	if err != nil {
+		t.Fatalf("Issue returned error: %v", err)
+	}
+	if _, err := before.Verify(newToken); !errors.Is(err, ErrPlaybackTokenInvalid) {
+		t.Fatalf("expected new tokens to be signed with the new key, got %v", err)
+	}
+
+	after := newTestPlaybackSigner(t, PlaybackSignerConfig{Key: "new", Now: clock})
+	if _, err := after.Verify(oldToken); !errors.Is(err, ErrPlaybackTokenInvalid) {
+		t.Fatalf("expected retired key to be rejected, got %v", err)
+	}
+	if _, err := after.Verify(newToken); err != nil {
+		t.Fatalf("expected current key to verify, got %v", err)
+	}
+}
+
+func TestNewPlaybackSignerRequiresKey(t *testing.T) {
+	if _, err := NewPlaybackSigner(PlaybackSignerConfig{Key: "  "}); err == nil {
+		t.Fatal("expected error for blank key")
+	}
+}
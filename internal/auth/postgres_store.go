@@ -98,21 +98,25 @@ func (s *PostgresSessionStore) Ping(ctx context.Context) error {
 }
 
 // Save stores or updates the session token.
-func (s *PostgresSessionStore) Save(token, userID string, expiresAt, absoluteExpiresAt time.Time) error {
+func (s *PostgresSessionStore) Save(record SessionRecord) error {
 	if s.pool == nil {
 		return fmt.Errorf("postgres session pool not configured")
 	}
-	hashedToken, err := hashSessionToken(token)
+	hashedToken, err := hashSessionToken(record.Token)
 	if err != nil {
 		return err
 	}
+	var impersonatorID any
+	if record.ImpersonatorID != "" {
+		impersonatorID = record.ImpersonatorID
+	}
 	ctx, cancel := s.operationContext()
 	defer cancel()
 	_, err = s.pool.Exec(ctx, `
-INSERT INTO auth_sessions (token, hashed_token, user_id, expires_at, absolute_expires_at)
-VALUES ($1, $2, $3, $4, $5)
-ON CONFLICT (hashed_token) DO UPDATE SET user_id = EXCLUDED.user_id, expires_at = EXCLUDED.expires_at, absolute_expires_at = EXCLUDED.absolute_expires_at
-`, hashedToken, hashedToken, userID, expiresAt.UTC(), absoluteExpiresAt.UTC())
+INSERT INTO auth_sessions (token, hashed_token, user_id, expires_at, absolute_expires_at, impersonator_id)
+VALUES ($1, $2, $3, $4, $5, $6)
+ON CONFLICT (hashed_token) DO UPDATE SET user_id = EXCLUDED.user_id, expires_at = EXCLUDED.expires_at, absolute_expires_at = EXCLUDED.absolute_expires_at, impersonator_id = EXCLUDED.impersonator_id
+`, hashedToken, hashedToken, record.UserID, record.ExpiresAt.UTC(), record.AbsoluteExpiresAt.UTC(), impersonatorID)
 	return err
 }
 
@@ -128,13 +132,13 @@ func (s *PostgresSessionStore) Get(token string) (SessionRecord, bool, error) {
 	ctx, cancel := s.operationContext()
 	defer cancel()
 	row := s.pool.QueryRow(ctx, `
-SELECT user_id, expires_at, absolute_expires_at
+SELECT user_id, expires_at, absolute_expires_at, COALESCE(impersonator_id, '')
 FROM auth_sessions
 WHERE hashed_token = $1
 `, hashedToken)
 	var record SessionRecord
 	record.Token = token
-	if err := row.Scan(&record.UserID, &record.ExpiresAt, &record.AbsoluteExpiresAt); err != nil {
+	if err := row.Scan(&record.UserID, &record.ExpiresAt, &record.AbsoluteExpiresAt, &record.ImpersonatorID); err != nil {
 		if isNoRows(err) {
 			return SessionRecord{}, false, nil
 		}
@@ -158,17 +162,52 @@ func (s *PostgresSessionStore) Delete(token string) error {
 	return err
 }
 
-// PurgeExpired deletes expired sessions from the table.
-func (s *PostgresSessionStore) PurgeExpired(now time.Time) error {
+// DeleteUser removes every session belonging to the user or impersonating
+// someone on their behalf.
+func (s *PostgresSessionStore) DeleteUser(userID string) error {
 	if s.pool == nil {
 		return fmt.Errorf("postgres session pool not configured")
 	}
 	ctx, cancel := s.operationContext()
 	defer cancel()
-	_, err := s.pool.Exec(ctx, `DELETE FROM auth_sessions WHERE expires_at <= $1 OR absolute_expires_at <= $1`, now.UTC())
+	_, err := s.pool.Exec(ctx, `DELETE FROM auth_sessions WHERE user_id = $1 OR impersonator_id = $1`, userID)
+	return err
+}
+
+// PurgeExpired deletes expired sessions from the table.
+func (s *PostgresSessionStore) PurgeExpired(now time.Time) error {
+	_, err := s.PurgeExpiredSessions(now)
 	return err
 }
 
+// PurgeExpiredSessions deletes expired sessions from the table and returns
+// them without their tokens, which are only stored hashed.
+func (s *PostgresSessionStore) PurgeExpiredSessions(now time.Time) ([]SessionRecord, error) {
+	if s.pool == nil {
+		return nil, fmt.Errorf("postgres session pool not configured")
+	}
+	ctx, cancel := s.operationContext()
+	defer cancel()
+	rows, err := s.pool.Query(ctx, `
+DELETE FROM auth_sessions
+WHERE expires_at <= $1 OR absolute_expires_at <= $1
+RETURNING user_id, expires_at, absolute_expires_at, COALESCE(impersonator_id, '')
+`, now.UTC())
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+	var expired []SessionRecord
+	for rows.Next() {
+		var record SessionRecord
+		if err := rows.Scan(&record.UserID, &record.ExpiresAt, &record.AbsoluteExpiresAt, &record.ImpersonatorID); err != nil {
+			return expired, err
+		}
+		expired = append(expired, record)
+	}
+	return expired, rows.Err()
+}
+
 func (s *PostgresSessionStore) operationContext() (context.Context, context.CancelFunc) {
 	if s.timeout > 0 {
 		return context.WithTimeout(context.Background(), s.timeout)
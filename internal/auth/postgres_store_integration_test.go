@@ -54,7 +54,7 @@ func TestPostgresSessionStoreTimeout(t *testing.T) {
 		_, _ = cleanupConn.Exec(cleanupCtx, `DROP FUNCTION IF EXISTS slow_auth_sessions_trigger()`)
 	}()
 
-	err = store.Save("timeout-token", "timeout-user", time.Now().Add(time.Hour), time.Now().Add(2*time.Hour))
+	err = store.Save(SessionRecord{Token: "timeout-token", UserID: "timeout-user", ExpiresAt: time.Now().Add(time.Hour), AbsoluteExpiresAt: time.Now().Add(2 * time.Hour)})
 	if err == nil {
 		t.Fatal("expected timeout error from slow trigger")
 	}
@@ -72,7 +72,7 @@ func TestPostgresSessionStoreSavesHashedTokens(t *testing.T) {
 	token := "raw-session-token"
 	expiresAt := time.Now().Add(time.Hour)
 
-	if err := store.Save(token, "user-id", expiresAt, expiresAt.Add(time.Hour)); err != nil {
+	if err := store.Save(SessionRecord{Token: token, UserID: "user-id", ExpiresAt: expiresAt, AbsoluteExpiresAt: expiresAt.Add(time.Hour)}); err != nil {
 		t.Fatalf("save session: %v", err)
 	}
 
@@ -130,7 +130,7 @@ func TestPostgresSessionStoreDeleteUsesHashes(t *testing.T) {
 	}
 
 	token := "token-to-delete"
-	if err := store.Save(token, "user-id", time.Now().Add(time.Hour), time.Now().Add(2*time.Hour)); err != nil {
+	if err := store.Save(SessionRecord{Token: token, UserID: "user-id", ExpiresAt: time.Now().Add(time.Hour), AbsoluteExpiresAt: time.Now().Add(2 * time.Hour)}); err != nil {
 		t.Fatalf("save session: %v", err)
 	}
 
@@ -258,3 +258,29 @@ func splitSQLStatementsForTest(script string) []string {
 	}
 	return statements
 }
+
+func TestPostgresSessionStoreImpersonation(t *testing.T) {
+	store, cleanup := openPostgresSessionStoreForTest(t)
+	if cleanup != nil {
+		defer cleanup()
+	}
+
+	expiresAt := time.Now().Add(time.Hour)
+	if err := store.Save(SessionRecord{Token: "impersonation-token", UserID: "target-user", ExpiresAt: expiresAt, AbsoluteExpiresAt: expiresAt, ImpersonatorID: "admin-user"}); err != nil {
+		t.Fatalf("save session: %v", err)
+	}
+	record, ok, err := store.Get("impersonation-token")
+	if err != nil || !ok {
+		t.Fatalf("get session: ok=%v err=%v", ok, err)
+	}
+	if record.UserID != "target-user" || record.ImpersonatorID != "admin-user" {
+		t.Fatalf("expected both identities, got %+v", record)
+	}
+
+	if err := store.DeleteUser("admin-user"); err != nil {
+		t.Fatalf("delete user sessions: %v", err)
+	}
+	if _, ok, err := store.Get("impersonation-token"); err != nil || ok {
+		t.Fatalf("expected revoking the admin to end the impersonation, ok=%v err=%v", ok, err)
+	}
+}
@@ -0,0 +1,271 @@
+package auth
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	redis "github.com/redis/go-redis/v9"
+)
+
+// RedisTLSConfig controls TLS behaviour for the Redis session store connection.
+type RedisTLSConfig struct {
+	CAFile             string
+	CertFile           string
+	KeyFile            string
+	ServerName         string
+	InsecureSkipVerify bool
+}
+
+// RedisSessionStoreConfig configures the Redis-backed session store. Addrs
+// lists sentinel addresses when MasterName is set.
+type RedisSessionStoreConfig struct {
+	Addr       string
+	Addrs      []string
+	Username   string
+	Password   string
+	MasterName string
+	PoolSize   int
+	KeyPrefix  string
+	Timeout    time.Duration
+	TLS        RedisTLSConfig
+}
+
+const (
+	defaultRedisSessionKeyPrefix = "bitriver:session:"
+	defaultRedisSessionTimeout   = 2 * time.Second
+)
+
+// RedisSessionStore keeps sessions in Redis hashes keyed by the hashed token.
+// Each key expires alongside its session, so Redis purges stale sessions on
+// its own and several API replicas can share authentication state without
+// querying Postgres on every request.
+type RedisSessionStore struct {
+	client  redis.UniversalClient
+	prefix  string
+	timeout time.Duration
+}
+
+// NewRedisSessionStore connects a RedisSessionStore using cfg.
+func NewRedisSessionStore(cfg RedisSessionStoreConfig) (*RedisSessionStore, error) {
+	addrs := make([]string, 0, len(cfg.Addrs)+1)
+	for _, addr := range cfg.Addrs {
+		if trimmed := strings.TrimSpace(addr); trimmed != "" {
+			addrs = append(addrs, trimmed)
+		}
+	}
+	if addr := strings.TrimSpace(cfg.Addr); addr != "" {
+		addrs = append(addrs, addr)
+	}
+	if len(addrs) == 0 {
+		return nil, fmt.Errorf("redis session store addr required")
+	}
+	tlsConfig, err := buildRedisTLSConfig(cfg.TLS)
+	if err != nil {
+		return nil, err
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultRedisSessionTimeout
+	}
+	client, err := redis.NewUniversalClient(&redis.UniversalOptions{
+		Addrs:        addrs,
+		MasterName:   strings.TrimSpace(cfg.MasterName),
+		Username:     strings.TrimSpace(cfg.Username),
+		Password:     cfg.Password,
+		TLSConfig:    tlsConfig,
+		PoolSize:     cfg.PoolSize,
+		DialTimeout:  timeout,
+		ReadTimeout:  timeout,
+		WriteTimeout: timeout,
+		MaxRetries:   1,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("open redis session store: %w", err)
+	}
+	prefix := strings.TrimSpace(cfg.KeyPrefix)
+	if prefix == "" {
+		prefix = defaultRedisSessionKeyPrefix
+	}
+	return &RedisSessionStore{client: client, prefix: prefix, timeout: timeout}, nil
+}
+
+// Close releases the Redis connection pool.
+func (s *RedisSessionStore) Close(context.Context) error {
+	if s == nil || s.client == nil {
+		return nil
+	}
+	return s.client.Close()
+}
+
+// Ping checks connectivity to Redis.
+func (s *RedisSessionStore) Ping(ctx context.Context) error {
+	if s == nil || s.client == nil {
+		return fmt.Errorf("redis session store not configured")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	_, err := s.client.Do(ctx, "PING")
+	return err
+}
+
+// Save stores the session and sets the key to expire with it. The hash write
+// and expiry are sent as one MULTI/EXEC pipeline so a refresh costs a single
+// round trip and a session can never be left without a TTL.
+func (s *RedisSessionStore) Save(record SessionRecord) error {
+	key, err := s.key(record.Token)
+	if err != nil {
+		return err
+	}
+	expireAt := record.ExpiresAt
+	if !record.AbsoluteExpiresAt.IsZero() && record.AbsoluteExpiresAt.Before(expireAt) {
+		expireAt = record.AbsoluteExpiresAt
+	}
+	ctx, cancel := s.operationContext()
+	defer cancel()
+	replies, err := s.client.Pipeline(ctx,
+		[]interface{}{"MULTI"},
+		[]interface{}{"HSET", key,
+			"user_id", record.UserID,
+			"expires_at", record.ExpiresAt.UTC().Format(time.RFC3339Nano),
+			"absolute_expires_at", record.AbsoluteExpiresAt.UTC().Format(time.RFC3339Nano),
+			"impersonator_id", record.ImpersonatorID,
+		},
+		[]interface{}{"PEXPIREAT", key, expireAt.UnixMilli()},
+		[]interface{}{"EXEC"},
+	)
+	if err != nil {
+		return fmt.Errorf("save redis session: %w", err)
+	}
+	if len(replies) != 4 {
+		return fmt.Errorf("save redis session: unexpected reply count %d", len(replies))
+	}
+	if _, ok := replies[3].([]interface{}); !ok {
+		return fmt.Errorf("save redis session: transaction aborted")
+	}
+	return nil
+}
+
+// Get fetches the session details for the provided token with a single
+// HGETALL.
+func (s *RedisSessionStore) Get(token string) (SessionRecord, bool, error) {
+	key, err := s.key(token)
+	if err != nil {
+		return SessionRecord{}, false, err
+	}
+	ctx, cancel := s.operationContext()
+	defer cancel()
+	reply, err := s.client.Do(ctx, "HGETALL", key)
+	if err != nil {
+		return SessionRecord{}, false, fmt.Errorf("get redis session: %w", err)
+	}
+	values, ok := reply.([]interface{})
+	if !ok {
+		return SessionRecord{}, false, fmt.Errorf("get redis session: unexpected reply type %T", reply)
+	}
+	if len(values) == 0 {
+		return SessionRecord{}, false, nil
+	}
+	fields := make(map[string]string, len(values)/2)
+	for i := 0; i+1 < len(values); i += 2 {
+		name, _ := redisString(values[i])
+		value, _ := redisString(values[i+1])
+		fields[name] = value
+	}
+	record := SessionRecord{Token: token, UserID: fields["user_id"], ImpersonatorID: fields["impersonator_id"]}
+	if record.UserID == "" {
+		return SessionRecord{}, false, fmt.Errorf("get redis session: missing user_id")
+	}
+	if record.ExpiresAt, err = time.Parse(time.RFC3339Nano, fields["expires_at"]); err != nil {
+		return SessionRecord{}, false, fmt.Errorf("get redis session: parse expires_at: %w", err)
+	}
+	if raw := fields["absolute_expires_at"]; raw != "" {
+		if record.AbsoluteExpiresAt, err = time.Parse(time.RFC3339Nano, raw); err != nil {
+			return SessionRecord{}, false, fmt.Errorf("get redis session: parse absolute_expires_at: %w", err)
+		}
+	}
+	return record, true, nil
+}
+
+// Delete removes the session token.
+func (s *RedisSessionStore) Delete(token string) error {
+	key, err := s.key(token)
+	if err != nil {
+		return err
+	}
+	ctx, cancel := s.operationContext()
+	defer cancel()
+	if _, err := s.client.Do(ctx, "DEL", key); err != nil {
+		return fmt.Errorf("delete redis session: %w", err)
+	}
+	return nil
+}
+
+// PurgeExpired is a no-op because Redis expires session keys itself.
+func (s *RedisSessionStore) PurgeExpired(time.Time) error {
+	return nil
+}
+
+func (s *RedisSessionStore) key(token string) (string, error) {
+	if s == nil || s.client == nil {
+		return "", fmt.Errorf("redis session store not configured")
+	}
+	hashedToken, err := hashSessionToken(token)
+	if err != nil {
+		return "", err
+	}
+	return s.prefix + hashedToken, nil
+}
+
+func (s *RedisSessionStore) operationContext() (context.Context, context.CancelFunc) {
+	return context.WithTimeout(context.Background(), s.timeout)
+}
+
+func redisString(value interface{}) (string, bool) {
+	switch v := value.(type) {
+	case string:
+		return v, true
+	case []byte:
+		return string(v), true
+	default:
+		return "", false
+	}
+}
+
+func buildRedisTLSConfig(cfg RedisTLSConfig) (*tls.Config, error) {
+	if cfg.CAFile == "" && cfg.CertFile == "" && cfg.KeyFile == "" && !cfg.InsecureSkipVerify {
+		return nil, nil
+	}
+	tlsCfg := &tls.Config{InsecureSkipVerify: cfg.InsecureSkipVerify}
+	if cfg.ServerName != "" {
+		tlsCfg.ServerName = cfg.ServerName
+	}
+	if cfg.CAFile != "" {
+		pemData, err := os.ReadFile(filepath.Clean(cfg.CAFile))
+		if err != nil {
+			return nil, fmt.Errorf("read redis tls ca: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pemData) {
+			return nil, errors.New("redis tls ca is invalid")
+		}
+		tlsCfg.RootCAs = pool
+	}
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		cert, err := tls.LoadX509KeyPair(filepath.Clean(cfg.CertFile), filepath.Clean(cfg.KeyFile))
+		if err != nil {
+			return nil, fmt.Errorf("load redis tls certificate: %w", err)
+		}
+		tlsCfg.Certificates = []tls.Certificate{cert}
+	}
+	return tlsCfg, nil
+}
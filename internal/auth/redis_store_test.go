@@ -0,0 +1,184 @@
+package auth
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/testsupport/redisstub"
+)
+
+func newRedisTestStore(t *testing.T) (*RedisSessionStore, *redisstub.Server) {
+	t.Helper()
+	srv, err := redisstub.Start(redisstub.Options{Password: "secret"})
+	if err != nil {
+		t.Fatalf("start redis stub: %v", err)
+	}
+	t.Cleanup(func() { _ = srv.Close() })
+	store, err := NewRedisSessionStore(RedisSessionStoreConfig{
+		Addr:     srv.Addr(),
+		Password: "secret",
+		Timeout:  time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewRedisSessionStore: %v", err)
+	}
+	t.Cleanup(func() { _ = store.Close(context.Background()) })
+	return store, srv
+}
+
+func redisSessionTTL(t *testing.T, store *RedisSessionStore, token string) time.Duration {
+	t.Helper()
+	key, err := store.key(token)
+	if err != nil {
+		t.Fatalf("key: %v", err)
+	}
+	ctx, cancel := store.operationContext()
+	defer cancel()
+	reply, err := store.client.Do(ctx, "PTTL", key)
+	if err != nil {
+		t.Fatalf("PTTL: %v", err)
+	}
+	millis, ok := reply.(int64)
+	if !ok {
+		t.Fatalf("unexpected PTTL reply %T", reply)
+	}
+	return time.Duration(millis) * time.Millisecond
+}
+
+func TestRedisSessionStoreLifecycle(t *testing.T) {
+	store, _ := newRedisTestStore(t)
+	manager := NewSessionManager(time.Hour, WithStore(store))
+
+	token, expiresAt, err := manager.Create("user-1")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	userID, validatedExpiry, ok, err := manager.Validate(token)
+	if err != nil || !ok {
+		t.Fatalf("Validate: ok=%v err=%v", ok, err)
+	}
+	if userID != "user-1" {
+		t.Fatalf("expected user-1, got %q", userID)
+	}
+	if !validatedExpiry.Equal(expiresAt.UTC()) {
+		t.Fatalf("expected expiry %v, got %v", expiresAt, validatedExpiry)
+	}
+	if ttl := redisSessionTTL(t, store, token); ttl <= 59*time.Minute || ttl > time.Hour {
+		t.Fatalf("expected key TTL near one hour, got %v", ttl)
+	}
+
+	if err := manager.Revoke(token); err != nil {
+		t.Fatalf("Revoke: %v", err)
+	}
+	if _, _, ok, err := manager.Validate(token); err != nil || ok {
+		t.Fatalf("expected revoked session to be invalid, ok=%v err=%v", ok, err)
+	}
+}
+
+func TestRedisSessionStoreKeysByHashedToken(t *testing.T) {
+	store, _ := newRedisTestStore(t)
+	if err := store.Save(SessionRecord{Token: "plain-token", UserID: "user-1", ExpiresAt: time.Now().Add(time.Hour), AbsoluteExpiresAt: time.Now().Add(time.Hour)}); err != nil {
+		t.Fatalf("Save: %v", err)
+	}
+	hashed, err := hashSessionToken("plain-token")
+	if err != nil {
+		t.Fatalf("hashSessionToken: %v", err)
+	}
+	ctx, cancel := store.operationContext()
+	defer cancel()
+	reply, err := store.client.Do(ctx, "HGETALL", defaultRedisSessionKeyPrefix+hashed)
+	if err != nil {
+		t.Fatalf("HGETALL: %v", err)
+	}
+	if values, ok := reply.([]interface{}); !ok || len(values) == 0 {
+		t.Fatalf("expected session stored under hashed token key, got %v", reply)
+	}
+	reply, err = store.client.Do(ctx, "HGETALL", defaultRedisSessionKeyPrefix+"plain-token")
+	if err != nil {
+		t.Fatalf("HGETALL: %v", err)
+	}
+	if values, ok := reply.([]interface{}); !ok || len(values) != 0 {
+		t.Fatalf("expected no key for the plaintext token, got %v", reply)
+	}
+}
+
+func TestRedisSessionStoreExpiresWithTTL(t *testing.T) {
+	store, srv := newRedisTestStore(t)
+	manager := NewSessionManager(time.Hour, WithStore(store), WithIdleTimeout(10*time.Minute))
+
+	token, _, err := manager.Create("user-1")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if ttl := redisSessionTTL(t, store, token); ttl <= 9*time.Minute || ttl > 10*time.Minute {
+		t.Fatalf("expected key TTL to follow the idle timeout, got %v", ttl)
+	}
+
+	srv.FastForward(5 * time.Minute)
+	if _, _, ok, err := manager.Validate(token); err != nil || !ok {
+		t.Fatalf("expected session to still be valid, ok=%v err=%v", ok, err)
+	}
+
+	srv.FastForward(11 * time.Minute)
+	if _, ok, err := store.Get(token); err != nil || ok {
+		t.Fatalf("expected Redis to expire the session key, ok=%v err=%v", ok, err)
+	}
+	if _, _, ok, err := manager.Validate(token); err != nil || ok {
+		t.Fatalf("expected expired session to be invalid, ok=%v err=%v", ok, err)
+	}
+}
+
+func TestRedisSessionStoreUnavailable(t *testing.T) {
+	store, srv := newRedisTestStore(t)
+	manager := NewSessionManager(time.Hour, WithStore(store))
+
+	token, _, err := manager.Create("user-1")
+	if err != nil {
+		t.Fatalf("Create: %v", err)
+	}
+	if err := srv.Close(); err != nil {
+		t.Fatalf("close redis stub: %v", err)
+	}
+
+	userID, _, ok, err := manager.Validate(token)
+	if !errors.Is(err, ErrSessionStoreUnavailable) {
+		t.Fatalf("expected ErrSessionStoreUnavailable, got %v", err)
+	}
+	if ok || userID != "" {
+		t.Fatalf("expected no session while the store is down, got ok=%v user=%q", ok, userID)
+	}
+	if err := store.Ping(context.Background()); err == nil {
+		t.Fatal("expected Ping to fail while the store is down")
+	}
+}
+
+func TestRedisSessionStoreRequiresAddr(t *testing.T) {
+	if _, err := NewRedisSessionStore(RedisSessionStoreConfig{}); err == nil {
+		t.Fatal("expected error without an address")
+	}
+	if _, err := NewRedisSessionStore(RedisSessionStoreConfig{Addrs: []string{" "}}); err == nil {
+		t.Fatal("expected error for blank addresses")
+	}
+}
+
+func TestRedisSessionStoreKeepsImpersonator(t *testing.T) {
+	store, _ := newRedisTestStore(t)
+	manager := NewSessionManager(time.Hour, WithStore(store))
+
+	token, _, err := manager.CreateImpersonation("admin-1", "user-1", time.Minute)
+	if err != nil {
+		t.Fatalf("CreateImpersonation: %v", err)
+	}
+	record, ok, err := manager.ValidateSession(token)
+	if err != nil || !ok {
+		t.Fatalf("ValidateSession: ok=%v err=%v", ok, err)
+	}
+	if record.UserID != "user-1" || record.ImpersonatorID != "admin-1" {
+		t.Fatalf("expected both identities to round-trip, got %+v", record)
+	}
+	if ttl := redisSessionTTL(t, store, token); ttl > time.Minute {
+		t.Fatalf("expected key TTL capped at the impersonation lifetime, got %v", ttl)
+	}
+}
@@ -5,12 +5,14 @@ import (
 	"crypto/rand"
 	"encoding/hex"
 	"errors"
+	"fmt"
+	"log/slog"
 	"time"
 )
 
 // SessionStore defines the persistence contract for session tokens.
 type SessionStore interface {
-	Save(token, userID string, expiresAt, absoluteExpiresAt time.Time) error
+	Save(record SessionRecord) error
 	Get(token string) (SessionRecord, bool, error)
 	Delete(token string) error
 	PurgeExpired(now time.Time) error
@@ -22,8 +24,21 @@ type SessionRecord struct {
 	UserID            string
 	ExpiresAt         time.Time
 	AbsoluteExpiresAt time.Time
+	// ImpersonatorID is the administrator acting as UserID when the session
+	// was issued by CreateImpersonation, and empty otherwise.
+	ImpersonatorID string
 }
 
+// Impersonated reports whether the session was issued to an administrator
+// acting as another user.
+func (r SessionRecord) Impersonated() bool {
+	return r.ImpersonatorID != ""
+}
+
+// DefaultImpersonationTTL is the lifetime of impersonation sessions when the
+// caller does not choose one. They are never extended by activity.
+const DefaultImpersonationTTL = 30 * time.Minute
+
 // SessionOption configures a SessionManager instance.
 type SessionOption func(*SessionManager)
 
@@ -54,6 +69,14 @@ func WithIdleTimeout(timeout time.Duration) SessionOption {
 	}
 }
 
+// WithAuditLogger records sessions removed by PurgeExpired on logger as
+// AuditActionSessionExpire events.
+func WithAuditLogger(logger *slog.Logger) SessionOption {
+	return func(m *SessionManager) {
+		m.auditLogger = logger
+	}
+}
+
 // SessionManager coordinates session creation and validation against a backing store.
 type SessionManager struct {
 	store        SessionStore
@@ -61,6 +84,7 @@ type SessionManager struct {
 	idleTimeout  time.Duration
 	tokenLength  int
 	tokenFactory func(int) (string, error)
+	auditLogger  *slog.Logger
 }
 
 // NewSessionManager constructs a SessionManager with the provided absolute TTL and options.
@@ -103,23 +127,61 @@ func (m *SessionManager) Create(userID string) (string, time.Time, error) {
 			expiresAt = absoluteExpiresAt
 		}
 	}
-	if err := m.store.Save(token, userID, expiresAt.UTC(), absoluteExpiresAt.UTC()); err != nil {
+	if err := m.store.Save(SessionRecord{Token: token, UserID: userID, ExpiresAt: expiresAt.UTC(), AbsoluteExpiresAt: absoluteExpiresAt.UTC()}); err != nil {
+		return "", time.Time{}, err
+	}
+	return token, expiresAt, nil
+}
+
+// CreateImpersonation issues a session that lets adminID act as userID. The
+// session lasts exactly ttl (DefaultImpersonationTTL when ttl is not
+// positive) and is not refreshed by the idle timeout.
+func (m *SessionManager) CreateImpersonation(adminID, userID string, ttl time.Duration) (string, time.Time, error) {
+	if adminID == "" || userID == "" {
+		return "", time.Time{}, ErrInvalidUserID
+	}
+	if adminID == userID {
+		return "", time.Time{}, fmt.Errorf("cannot impersonate yourself")
+	}
+	if ttl <= 0 {
+		ttl = DefaultImpersonationTTL
+	}
+	token, err := m.tokenFactory(m.tokenLength)
+	if err != nil {
+		return "", time.Time{}, err
+	}
+	expiresAt := time.Now().Add(ttl).UTC()
+	record := SessionRecord{Token: token, UserID: userID, ExpiresAt: expiresAt, AbsoluteExpiresAt: expiresAt, ImpersonatorID: adminID}
+	if err := m.store.Save(record); err != nil {
 		return "", time.Time{}, err
 	}
 	return token, expiresAt, nil
 }
 
 // Validate checks the backing store for the provided token and returns the associated user when valid.
+// Store failures are wrapped in ErrSessionStoreUnavailable so callers can tell an outage apart from
+// an invalid session instead of logging the user out.
 func (m *SessionManager) Validate(token string) (string, time.Time, bool, error) {
+	record, ok, err := m.ValidateSession(token)
+	if err != nil || !ok {
+		return "", time.Time{}, false, err
+	}
+	return record.UserID, record.ExpiresAt, true, nil
+}
+
+// ValidateSession behaves like Validate but returns the whole session record,
+// including the impersonating administrator, with ExpiresAt reflecting any
+// idle refresh.
+func (m *SessionManager) ValidateSession(token string) (SessionRecord, bool, error) {
 	if token == "" {
-		return "", time.Time{}, false, nil
+		return SessionRecord{}, false, nil
 	}
 	record, ok, err := m.store.Get(token)
 	if err != nil {
-		return "", time.Time{}, false, err
+		return SessionRecord{}, false, fmt.Errorf("%w: %w", ErrSessionStoreUnavailable, err)
 	}
 	if !ok {
-		return "", time.Time{}, false, nil
+		return SessionRecord{}, false, nil
 	}
 	now := time.Now()
 	absoluteExpiresAt := record.AbsoluteExpiresAt
@@ -128,22 +190,24 @@ func (m *SessionManager) Validate(token string) (string, time.Time, bool, error)
 	}
 	if now.After(record.ExpiresAt) || now.After(absoluteExpiresAt) {
 		_ = m.store.Delete(token)
-		return "", time.Time{}, false, nil
+		return SessionRecord{}, false, nil
 	}
-	expiresAt := record.ExpiresAt
-	if m.idleTimeout > 0 {
+	if m.idleTimeout > 0 && !record.Impersonated() {
 		refreshTo := now.Add(m.idleTimeout)
 		if refreshTo.After(absoluteExpiresAt) {
 			refreshTo = absoluteExpiresAt
 		}
 		if refreshTo.After(record.ExpiresAt) {
-			if err := m.store.Save(record.Token, record.UserID, refreshTo.UTC(), absoluteExpiresAt.UTC()); err != nil {
-				return "", time.Time{}, false, err
+			refreshed := record
+			refreshed.ExpiresAt = refreshTo.UTC()
+			refreshed.AbsoluteExpiresAt = absoluteExpiresAt.UTC()
+			if err := m.store.Save(refreshed); err != nil {
+				return SessionRecord{}, false, fmt.Errorf("%w: %w", ErrSessionStoreUnavailable, err)
 			}
-			expiresAt = refreshTo
+			record.ExpiresAt = refreshTo
 		}
 	}
-	return record.UserID, expiresAt, true, nil
+	return record, true, nil
 }
 
 // Revoke deletes the session token from the backing store.
@@ -154,9 +218,50 @@ func (m *SessionManager) Revoke(token string) error {
 	return m.store.Delete(token)
 }
 
-// PurgeExpired removes any expired sessions from the backing store.
+// userSessionDeleter is implemented by stores that can find every session
+// belonging to a user, including the impersonation sessions the user started.
+type userSessionDeleter interface {
+	DeleteUser(userID string) error
+}
+
+// RevokeUser deletes every session held by the user, along with any session
+// in which the user is impersonating someone else. Stores that cannot look
+// sessions up by user keep them, so callers must still refuse the user when
+// their sessions are validated.
+func (m *SessionManager) RevokeUser(userID string) error {
+	if userID == "" {
+		return nil
+	}
+	if deleter, ok := m.store.(userSessionDeleter); ok {
+		return deleter.DeleteUser(userID)
+	}
+	return nil
+}
+
+// expiredSessionPurger is implemented by stores that can report the
+// sessions their purge removed.
+type expiredSessionPurger interface {
+	PurgeExpiredSessions(now time.Time) ([]SessionRecord, error)
+}
+
+// PurgeExpired removes any expired sessions from the backing store. With an
+// audit logger configured, each removed session is audited when the store
+// can report them; Redis expires sessions on its own, so none are reported
+// there.
 func (m *SessionManager) PurgeExpired() error {
-	return m.store.PurgeExpired(time.Now())
+	purger, ok := m.store.(expiredSessionPurger)
+	if m.auditLogger == nil || !ok {
+		return m.store.PurgeExpired(time.Now())
+	}
+	expired, err := purger.PurgeExpiredSessions(time.Now())
+	for _, record := range expired {
+		EmitAudit(context.Background(), m.auditLogger, AuditEvent{
+			Action:         AuditActionSessionExpire,
+			UserID:         record.UserID,
+			ImpersonatorID: record.ImpersonatorID,
+		})
+	}
+	return err
 }
 
 // Ping verifies the underlying session store is reachable when it exposes a ping method.
@@ -184,5 +289,8 @@ func generateToken(length int) (string, error) {
 	return hex.EncodeToString(bytes), nil
 }
 
+// ErrSessionStoreUnavailable wraps errors returned by the backing store while validating a session.
+var ErrSessionStoreUnavailable = errors.New("session store unavailable")
+
 // ErrInvalidUserID is returned when attempting to create a session without a user identifier.
 var ErrInvalidUserID = errors.New("userID is required")
@@ -1,7 +1,10 @@
 package auth
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
+	"log/slog"
 	"sync"
 	"testing"
 	"time"
@@ -70,6 +73,34 @@ func TestSessionExpiration(t *testing.T) {
 	}
 }
 
+func TestPurgeExpiredAuditsRemovedSessions(t *testing.T) {
+	var audit bytes.Buffer
+	manager := NewSessionManager(10*time.Millisecond, WithStore(NewMemorySessionStore()), WithAuditLogger(slog.New(slog.NewJSONHandler(&audit, nil))))
+	if _, _, err := manager.Create("user-123"); err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	time.Sleep(20 * time.Millisecond)
+	if err := manager.PurgeExpired(); err != nil {
+		t.Fatalf("PurgeExpired returned error: %v", err)
+	}
+	var event map[string]any
+	if err := json.Unmarshal(audit.Bytes(), &event); err != nil {
+		t.Fatalf("decode audit event %q: %v", audit.String(), err)
+	}
+	if event["action"] != AuditActionSessionExpire || event["user_id"] != "user-123" {
+		t.Fatalf("expected a session expiry event for user-123, got %v", event)
+	}
+
+	audit.Reset()
+	if err := manager.PurgeExpired(); err != nil {
+		t.Fatalf("PurgeExpired returned error: %v", err)
+	}
+	if audit.Len() != 0 {
+		t.Fatalf("expected nothing to be audited once the session was purged, got %s", audit.String())
+	}
+}
+
 func TestCreateRequiresUserID(t *testing.T) {
 	manager := NewSessionManager(time.Minute)
 	if _, _, err := manager.Create(""); err == nil {
@@ -190,3 +221,85 @@ func TestValidateHonorsAbsoluteTTL(t *testing.T) {
 		t.Fatalf("expected refresh to use absolute expiry %v, got %v", absoluteExpiry, refreshed)
 	}
 }
+
+func TestRevokeUserDeletesOnlyTheirSessions(t *testing.T) {
+	manager := NewSessionManager(time.Hour)
+	first, _, err := manager.Create("user-a")
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	second, _, err := manager.Create("user-a")
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+	other, _, err := manager.Create("user-b")
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	if err := manager.RevokeUser("user-a"); err != nil {
+		t.Fatalf("RevokeUser returned error: %v", err)
+	}
+	for _, token := range []string{first, second} {
+		if _, _, ok, err := manager.Validate(token); err != nil || ok {
+			t.Fatalf("expected user-a session to be revoked, got ok=%v err=%v", ok, err)
+		}
+	}
+	if _, _, ok, err := manager.Validate(other); err != nil || !ok {
+		t.Fatalf("expected user-b session to survive, got ok=%v err=%v", ok, err)
+	}
+}
+
+func TestImpersonationSessionsHaveFixedLifetime(t *testing.T) {
+	store := NewMemorySessionStore()
+	manager := NewSessionManager(time.Hour, WithStore(store), WithIdleTimeout(time.Hour))
+
+	if _, _, err := manager.CreateImpersonation("admin-1", "admin-1", time.Minute); err == nil {
+		t.Fatal("expected self impersonation to be rejected")
+	}
+	if _, _, err := manager.CreateImpersonation("", "user-1", time.Minute); err != ErrInvalidUserID {
+		t.Fatalf("expected ErrInvalidUserID without an admin, got %v", err)
+	}
+
+	token, expiresAt, err := manager.CreateImpersonation("admin-1", "user-1", 60*time.Millisecond)
+	if err != nil {
+		t.Fatalf("CreateImpersonation returned error: %v", err)
+	}
+	record, ok, err := manager.ValidateSession(token)
+	if err != nil || !ok {
+		t.Fatalf("expected impersonation session to validate, ok=%v err=%v", ok, err)
+	}
+	if record.UserID != "user-1" || record.ImpersonatorID != "admin-1" || !record.Impersonated() {
+		t.Fatalf("expected both identities on the session, got %+v", record)
+	}
+	if !record.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("expected the idle timeout not to extend the session past %v, got %v", expiresAt, record.ExpiresAt)
+	}
+
+	time.Sleep(80 * time.Millisecond)
+	if _, ok, err := manager.ValidateSession(token); err != nil || ok {
+		t.Fatalf("expected impersonation session to expire, ok=%v err=%v", ok, err)
+	}
+}
+
+func TestRevokeUserEndsImpersonationsByTheAdmin(t *testing.T) {
+	manager := NewSessionManager(time.Hour)
+	impersonation, _, err := manager.CreateImpersonation("admin-1", "user-1", time.Minute)
+	if err != nil {
+		t.Fatalf("CreateImpersonation returned error: %v", err)
+	}
+	own, _, err := manager.Create("user-1")
+	if err != nil {
+		t.Fatalf("Create returned error: %v", err)
+	}
+
+	if err := manager.RevokeUser("admin-1"); err != nil {
+		t.Fatalf("RevokeUser returned error: %v", err)
+	}
+	if _, _, ok, _ := manager.Validate(impersonation); ok {
+		t.Fatal("expected the admin's impersonation session to be revoked")
+	}
+	if _, _, ok, _ := manager.Validate(own); !ok {
+		t.Fatal("expected the impersonated user's own session to survive")
+	}
+}
@@ -0,0 +1,123 @@
+// Package authz maps the roles stored on models.User to the permissions that
+// handlers enforce. Handlers ask whether a user holds a permission rather than
+// checking role names, so adding a role only means extending the matrix here.
+//
+// Channel-scoped rules (ownership and per-channel editor grants) are layered
+// on top by the callers; the permissions in this package are platform-wide.
+package authz
+
+import (
+	"sort"
+	"strings"
+
+	"bitriver-live/internal/models"
+)
+
+// Permission names a platform-wide capability.
+type Permission string
+
+const (
+	// UsersManage allows listing, creating, updating, and deleting accounts
+	// and editing other users' profiles.
+	UsersManage Permission = "users.manage"
+	// ChannelsCreate allows creating channels and managing the ones the user owns.
+	ChannelsCreate Permission = "channels.create"
+	// ChannelsManageAny allows managing channels owned by other users.
+	ChannelsManageAny Permission = "channels.manage_any"
+	// ChatModerateAny allows moderating chat in every channel.
+	ChatModerateAny Permission = "chat.moderate_any"
+	// ReportsResolveAny allows reviewing and resolving reports for every
+	// channel, including the cross-channel moderation queue.
+	ReportsResolveAny Permission = "reports.resolve_any"
+	// RecordingsManageAny allows managing recordings and uploads for every channel.
+	RecordingsManageAny Permission = "recordings.manage_any"
+	// RecordingsManageGranted allows managing recordings and uploads for
+	// channels where the user holds an editor grant.
+	RecordingsManageGranted Permission = "recordings.manage_granted"
+	// MonetizationViewAny allows viewing tips and subscriptions for every channel.
+	MonetizationViewAny Permission = "monetization.view_any"
+	// AnalyticsView allows reading platform-wide analytics.
+	AnalyticsView Permission = "analytics.view"
+	// PlatformManage allows operating platform controls such as maintenance mode.
+	PlatformManage Permission = "platform.manage"
+)
+
+const (
+	// RoleAdmin is a site-wide administrator holding every permission.
+	RoleAdmin = "admin"
+	// RoleCreator owns and manages one or more channels.
+	RoleCreator = "creator"
+	// RoleModerator moderates chat and resolves reports across channels but
+	// cannot manage users.
+	RoleModerator = "moderator"
+	// RoleEditor manages recordings and uploads for channels that granted
+	// them editor access.
+	RoleEditor = "editor"
+)
+
+var allPermissions = []Permission{
+	UsersManage,
+	ChannelsCreate,
+	ChannelsManageAny,
+	ChatModerateAny,
+	ReportsResolveAny,
+	RecordingsManageAny,
+	RecordingsManageGranted,
+	MonetizationViewAny,
+	AnalyticsView,
+	PlatformManage,
+}
+
+var rolePermissions = map[string][]Permission{
+	RoleAdmin:     allPermissions,
+	RoleCreator:   {ChannelsCreate},
+	RoleModerator: {ChatModerateAny, ReportsResolveAny},
+	RoleEditor:    {RecordingsManageGranted},
+}
+
+// AllPermissions returns every known permission.
+func AllPermissions() []Permission {
+	return append([]Permission(nil), allPermissions...)
+}
+
+// Roles returns the roles that carry permissions, sorted by name.
+func Roles() []string {
+	roles := make([]string, 0, len(rolePermissions))
+	for role := range rolePermissions {
+		roles = append(roles, role)
+	}
+	sort.Strings(roles)
+	return roles
+}
+
+// RoleHas reports whether the role grants the permission. Unknown roles grant
+// nothing.
+func RoleHas(role string, permission Permission) bool {
+	for _, granted := range rolePermissions[strings.ToLower(strings.TrimSpace(role))] {
+		if granted == permission {
+			return true
+		}
+	}
+	return false
+}
+
+// Has reports whether any of the user's roles grants the permission.
+func Has(user models.User, permission Permission) bool {
+	for _, role := range user.Roles {
+		if RoleHas(role, permission) {
+			return true
+		}
+	}
+	return false
+}
+
+// CanModerateChannel reports whether the user may moderate chat in the channel.
+func CanModerateChannel(user models.User, channel models.Channel) bool {
+	return user.ID != "" && (channel.OwnerID == user.ID || Has(user, ChatModerateAny))
+}
+
+// CanResolveChannelReports reports whether the user may review and resolve
+// reports filed against the channel.
+func CanResolveChannelReports(user models.User, channel models.Channel) bool {
+	return user.ID != "" && (channel.OwnerID == user.ID || Has(user, ReportsResolveAny))
+}
@@ -0,0 +1,63 @@
+package authz
+
+import (
+	"testing"
+
+	"bitriver-live/internal/models"
+)
+
+func TestRolePermissionMatrix(t *testing.T) {
+	expected := map[string]map[Permission]bool{
+		RoleAdmin: {
+			UsersManage: true, ChannelsCreate: true, ChannelsManageAny: true, ChatModerateAny: true,
+			ReportsResolveAny: true, RecordingsManageAny: true, RecordingsManageGranted: true,
+			MonetizationViewAny: true, AnalyticsView: true, PlatformManage: true,
+		},
+		RoleCreator:   {ChannelsCreate: true},
+		RoleModerator: {ChatModerateAny: true, ReportsResolveAny: true},
+		RoleEditor:    {RecordingsManageGranted: true},
+		"viewer":      {},
+	}
+	for role, granted := range expected {
+		for _, permission := range AllPermissions() {
+			if got := RoleHas(role, permission); got != granted[permission] {
+				t.Errorf("RoleHas(%q, %q) = %v, want %v", role, permission, got, granted[permission])
+			}
+		}
+	}
+	for _, role := range Roles() {
+		if _, ok := expected[role]; !ok {
+			t.Errorf("role %q is missing from the expected matrix", role)
+		}
+	}
+}
+
+func TestHasChecksEveryRole(t *testing.T) {
+	user := models.User{ID: "u1", Roles: []string{"viewer", "Moderator"}}
+	if !Has(user, ReportsResolveAny) {
+		t.Fatal("expected moderator role to grant report resolution regardless of case")
+	}
+	if Has(user, UsersManage) {
+		t.Fatal("expected moderator not to manage users")
+	}
+}
+
+func TestChannelScopedChecks(t *testing.T) {
+	channel := models.Channel{ID: "c1", OwnerID: "owner"}
+	owner := models.User{ID: "owner", Roles: []string{RoleCreator}}
+	moderator := models.User{ID: "mod", Roles: []string{RoleModerator}}
+	editor := models.User{ID: "editor", Roles: []string{RoleEditor}}
+
+	if !CanModerateChannel(owner, channel) || !CanResolveChannelReports(owner, channel) {
+		t.Fatal("expected owner to moderate their channel")
+	}
+	if !CanModerateChannel(moderator, channel) || !CanResolveChannelReports(moderator, channel) {
+		t.Fatal("expected platform moderator to moderate any channel")
+	}
+	if CanModerateChannel(editor, channel) || CanResolveChannelReports(editor, channel) {
+		t.Fatal("expected editor not to moderate chat")
+	}
+	if CanModerateChannel(models.User{}, models.Channel{}) {
+		t.Fatal("expected anonymous user not to match an unowned channel")
+	}
+}
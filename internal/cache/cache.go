@@ -0,0 +1,166 @@
+package cache
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"bitriver-live/internal/observability/metrics"
+)
+
+// DefaultTTL bounds how long a cached payload is served when Config.TTL is
+// unset. Writes invalidate entries explicitly; the TTL only limits staleness
+// from changes the API does not observe.
+const DefaultTTL = 5 * time.Second
+
+// Backend stores cached payloads alongside the generation counters used for
+// invalidation. Generations of names that were never bumped are zero.
+type Backend interface {
+	Get(ctx context.Context, key string) ([]byte, bool, error)
+	Set(ctx context.Context, key string, value []byte, ttl time.Duration) error
+	Generations(ctx context.Context, names ...string) ([]int64, error)
+	Bump(ctx context.Context, name string) error
+}
+
+// Config controls a Cache. Backend defaults to an in-process MemoryBackend
+// driven by Now, TTL defaults to DefaultTTL, and Metrics defaults to
+// metrics.Default.
+type Config struct {
+	Backend    Backend
+	TTL        time.Duration
+	MaxEntries int
+	Now        func() time.Time
+	Metrics    *metrics.Recorder
+}
+
+// Cache serves encoded payloads from a Backend and loads them on a miss. A nil
+// *Cache is valid and always loads, which is how callers disable caching.
+type Cache struct {
+	backend Backend
+	ttl     time.Duration
+	metrics *metrics.Recorder
+
+	mu    sync.Mutex
+	calls map[string]*call
+}
+
+type call struct {
+	done    chan struct{}
+	value   []byte
+	err     error
+	waiters int
+}
+
+// New constructs a Cache from cfg.
+func New(cfg Config) *Cache {
+	backend := cfg.Backend
+	if backend == nil {
+		backend = NewMemoryBackend(cfg.Now, cfg.MaxEntries)
+	}
+	ttl := cfg.TTL
+	if ttl <= 0 {
+		ttl = DefaultTTL
+	}
+	recorder := cfg.Metrics
+	if recorder == nil {
+		recorder = metrics.Default()
+	}
+	return &Cache{
+		backend: backend,
+		ttl:     ttl,
+		metrics: recorder,
+		calls:   make(map[string]*call),
+	}
+}
+
+// GetOrLoad returns the cached payload for key within namespace, calling load
+// and caching its result on a miss. Concurrent misses for the same key share a
+// single load. Load errors are returned to every waiter and never cached.
+// Backend failures are treated as misses so an unavailable cache degrades to
+// uncached reads.
+func (c *Cache) GetOrLoad(ctx context.Context, namespace, key string, load func() ([]byte, error)) ([]byte, error) {
+	if c == nil {
+		return load()
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+
+	generations, err := c.backend.Generations(ctx, namespaceGeneration(namespace), keyGeneration(namespace, key))
+	if err != nil || len(generations) != 2 {
+		c.metrics.ObserveCacheLookup(namespace, "miss")
+		return c.do(fmt.Sprintf("%s|%s", namespace, key), load)
+	}
+	entryKey := fmt.Sprintf("entry:%s:%d:%s:%d", namespace, generations[0], key, generations[1])
+
+	if value, ok, err := c.backend.Get(ctx, entryKey); err == nil && ok {
+		c.metrics.ObserveCacheLookup(namespace, "hit")
+		return value, nil
+	}
+	c.metrics.ObserveCacheLookup(namespace, "miss")
+
+	return c.do(entryKey, func() ([]byte, error) {
+		value, err := load()
+		if err != nil {
+			return nil, err
+		}
+		_ = c.backend.Set(context.WithoutCancel(ctx), entryKey, value, c.ttl)
+		return value, nil
+	})
+}
+
+// Invalidate discards every cached payload in namespace.
+func (c *Cache) Invalidate(ctx context.Context, namespace string) error {
+	if c == nil {
+		return nil
+	}
+	return c.backend.Bump(contextOrBackground(ctx), namespaceGeneration(namespace))
+}
+
+// InvalidateKey discards the cached payload for key within namespace.
+func (c *Cache) InvalidateKey(ctx context.Context, namespace, key string) error {
+	if c == nil {
+		return nil
+	}
+	return c.backend.Bump(contextOrBackground(ctx), keyGeneration(namespace, key))
+}
+
+// do runs fn once per key among concurrent callers and hands its result to
+// every caller waiting on the same key.
+func (c *Cache) do(key string, fn func() ([]byte, error)) ([]byte, error) {
+	c.mu.Lock()
+	if existing, ok := c.calls[key]; ok {
+		existing.waiters++
+		c.mu.Unlock()
+		<-existing.done
+		return existing.value, existing.err
+	}
+	pending := &call{done: make(chan struct{})}
+	c.calls[key] = pending
+	c.mu.Unlock()
+
+	defer func() {
+		c.mu.Lock()
+		delete(c.calls, key)
+		c.mu.Unlock()
+		close(pending.done)
+	}()
+	pending.value, pending.err = fn()
+	return pending.value, pending.err
+}
+
+func namespaceGeneration(namespace string) string {
+	return "gen:" + namespace
+}
+
+func keyGeneration(namespace, key string) string {
+	return "gen:" + namespace + ":" + key
+}
+
+func contextOrBackground(ctx context.Context) context.Context {
+	if ctx == nil {
+		return context.Background()
+	}
+	return ctx
+}
@@ -0,0 +1,234 @@
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/observability/metrics"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func countingLoader(calls *atomic.Int64) func() ([]byte, error) {
+	return func() ([]byte, error) {
+		n := calls.Add(1)
+		return []byte(fmt.Sprintf("v%d", n)), nil
+	}
+}
+
+func TestGetOrLoadExpiresAfterTTL(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)}
+	c := New(Config{TTL: 10 * time.Second, Now: clock.Now, Metrics: metrics.New()})
+	var calls atomic.Int64
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		value, err := c.GetOrLoad(ctx, "directory", "all", countingLoader(&calls))
+		if err != nil {
+			t.Fatalf("GetOrLoad: %v", err)
+		}
+		if string(value) != "v1" {
+			t.Fatalf("expected cached v1, got %q", value)
+		}
+		clock.Advance(4 * time.Second)
+	}
+
+	value, err := c.GetOrLoad(ctx, "directory", "all", countingLoader(&calls))
+	if err != nil {
+		t.Fatalf("GetOrLoad: %v", err)
+	}
+	if string(value) != "v2" || calls.Load() != 2 {
+		t.Fatalf("expected reload after TTL, got %q after %d loads", value, calls.Load())
+	}
+}
+
+func TestInvalidateNamespaceAndKey(t *testing.T) {
+	c := New(Config{TTL: time.Hour, Metrics: metrics.New()})
+	ctx := context.Background()
+	var dirCalls, chanA, chanB atomic.Int64
+
+	load := func() {
+		t.Helper()
+		for _, step := range []struct {
+			ns, key string
+			calls   *atomic.Int64
+		}{
+			{"directory", "live", &dirCalls},
+			{"channel", "a", &chanA},
+			{"channel", "b", &chanB},
+		} {
+			if _, err := c.GetOrLoad(ctx, step.ns, step.key, countingLoader(step.calls)); err != nil {
+				t.Fatalf("GetOrLoad(%s, %s): %v", step.ns, step.key, err)
+			}
+		}
+	}
+
+	load()
+	load()
+	if dirCalls.Load() != 1 || chanA.Load() != 1 || chanB.Load() != 1 {
+		t.Fatalf("expected one load each, got %d/%d/%d", dirCalls.Load(), chanA.Load(), chanB.Load())
+	}
+
+	if err := c.InvalidateKey(ctx, "channel", "a"); err != nil {
+		t.Fatalf("InvalidateKey: %v", err)
+	}
+	load()
+	if dirCalls.Load() != 1 || chanA.Load() != 2 || chanB.Load() != 1 {
+		t.Fatalf("expected only channel a to reload, got %d/%d/%d", dirCalls.Load(), chanA.Load(), chanB.Load())
+	}
+
+	if err := c.Invalidate(ctx, "channel"); err != nil {
+		t.Fatalf("Invalidate: %v", err)
+	}
+	load()
+	if dirCalls.Load() != 1 || chanA.Load() != 3 || chanB.Load() != 2 {
+		t.Fatalf("expected every channel key to reload, got %d/%d/%d", dirCalls.Load(), chanA.Load(), chanB.Load())
+	}
+}
+
+func TestGetOrLoadCollapsesConcurrentMisses(t *testing.T) {
+	c := New(Config{TTL: time.Minute, Metrics: metrics.New()})
+	release := make(chan struct{})
+	started := make(chan struct{})
+	var calls atomic.Int64
+
+	const waiters = 16
+	var wg sync.WaitGroup
+	results := make([]string, waiters)
+	for i := 0; i < waiters; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			value, err := c.GetOrLoad(context.Background(), "directory", "trending", func() ([]byte, error) {
+				if calls.Add(1) == 1 {
+					close(started)
+				}
+				<-release
+				return []byte("payload"), nil
+			})
+			if err != nil {
+				t.Errorf("GetOrLoad: %v", err)
+				return
+			}
+			results[i] = string(value)
+		}(i)
+	}
+
+	<-started
+	deadline := time.Now().Add(5 * time.Second)
+	for inFlightWaiters(c) < waiters-1 {
+		if time.Now().After(deadline) {
+			t.Fatalf("only %d callers joined the in-flight load", inFlightWaiters(c))
+		}
+		time.Sleep(time.Millisecond)
+	}
+	close(release)
+	wg.Wait()
+
+	if calls.Load() != 1 {
+		t.Fatalf("expected a single load, got %d", calls.Load())
+	}
+	for i, value := range results {
+		if value != "payload" {
+			t.Fatalf("waiter %d got %q", i, value)
+		}
+	}
+}
+
+func inFlightWaiters(c *Cache) int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	total := 0
+	for _, pending := range c.calls {
+		total += pending.waiters
+	}
+	return total
+}
+
+func TestGetOrLoadDoesNotCacheErrors(t *testing.T) {
+	c := New(Config{TTL: time.Minute, Metrics: metrics.New()})
+	failure := errors.New("database unavailable")
+	attempts := 0
+	load := func() ([]byte, error) {
+		attempts++
+		if attempts == 1 {
+			return nil, failure
+		}
+		return []byte("ok"), nil
+	}
+
+	if _, err := c.GetOrLoad(context.Background(), "channel", "a", load); !errors.Is(err, failure) {
+		t.Fatalf("expected load error, got %v", err)
+	}
+	value, err := c.GetOrLoad(context.Background(), "channel", "a", load)
+	if err != nil || string(value) != "ok" {
+		t.Fatalf("expected retry to load ok, got %q, %v", value, err)
+	}
+}
+
+func TestNilCacheAlwaysLoads(t *testing.T) {
+	var c *Cache
+	var calls atomic.Int64
+	for i := 0; i < 2; i++ {
+		if _, err := c.GetOrLoad(context.Background(), "directory", "all", countingLoader(&calls)); err != nil {
+			t.Fatalf("GetOrLoad: %v", err)
+		}
+	}
+	if calls.Load() != 2 {
+		t.Fatalf("expected disabled cache to load every time, got %d", calls.Load())
+	}
+	if err := c.Invalidate(context.Background(), "directory"); err != nil {
+		t.Fatalf("Invalidate on nil cache: %v", err)
+	}
+}
+
+func TestGetOrLoadRecordsHitsAndMisses(t *testing.T) {
+	recorder := metrics.New()
+	c := New(Config{TTL: time.Minute, Metrics: recorder})
+	var calls atomic.Int64
+	for i := 0; i < 3; i++ {
+		if _, err := c.GetOrLoad(context.Background(), "directory", "all", countingLoader(&calls)); err != nil {
+			t.Fatalf("GetOrLoad: %v", err)
+		}
+	}
+	counts := recorder.CacheLookupCounts()
+	if hits := counts[metrics.CacheLookupLabel{Namespace: "directory", Result: "hit"}]; hits != 2 {
+		t.Fatalf("expected 2 hits, got %d", hits)
+	}
+	if misses := counts[metrics.CacheLookupLabel{Namespace: "directory", Result: "miss"}]; misses != 1 {
+		t.Fatalf("expected 1 miss, got %d", misses)
+	}
+}
+
+func TestMemoryBackendBoundsEntries(t *testing.T) {
+	backend := NewMemoryBackend(nil, 8)
+	ctx := context.Background()
+	for i := 0; i < 100; i++ {
+		if err := backend.Set(ctx, fmt.Sprintf("k%d", i), []byte("v"), time.Minute); err != nil {
+			t.Fatalf("Set: %v", err)
+		}
+	}
+	if n := backend.Len(); n > 8 {
+		t.Fatalf("expected at most 8 entries, got %d", n)
+	}
+}
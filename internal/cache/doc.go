@@ -0,0 +1,11 @@
+// Package cache provides the short-lived read cache placed in front of hot,
+// public API reads such as directory listings and channel pages.
+//
+// Payloads are cached as encoded bytes so the same entries can live in process
+// memory or in Redis when several API replicas share one. Invalidation works by
+// bumping generation counters instead of deleting keys: a lookup folds the
+// current namespace and key generations into the storage key, so entries
+// written before an invalidation simply stop being addressed and age out with
+// their TTL. Concurrent misses for the same key are collapsed into a single
+// load so an expired entry under load reaches the database once.
+package cache
@@ -0,0 +1,114 @@
+package cache
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// DefaultMaxEntries caps the number of payloads a MemoryBackend retains when no
+// limit is configured.
+const DefaultMaxEntries = 4096
+
+// MemoryBackend keeps cached payloads in process memory. It is the default
+// backend and suits single-replica deployments.
+type MemoryBackend struct {
+	mu          sync.Mutex
+	now         func() time.Time
+	maxEntries  int
+	entries     map[string]memoryEntry
+	generations map[string]int64
+}
+
+type memoryEntry struct {
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewMemoryBackend constructs a MemoryBackend. A nil now uses time.Now and a
+// non-positive maxEntries uses DefaultMaxEntries.
+func NewMemoryBackend(now func() time.Time, maxEntries int) *MemoryBackend {
+	if now == nil {
+		now = time.Now
+	}
+	if maxEntries <= 0 {
+		maxEntries = DefaultMaxEntries
+	}
+	return &MemoryBackend{
+		now:         now,
+		maxEntries:  maxEntries,
+		entries:     make(map[string]memoryEntry),
+		generations: make(map[string]int64),
+	}
+}
+
+func (b *MemoryBackend) Get(_ context.Context, key string) ([]byte, bool, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	entry, ok := b.entries[key]
+	if !ok {
+		return nil, false, nil
+	}
+	if !b.now().Before(entry.expiresAt) {
+		delete(b.entries, key)
+		return nil, false, nil
+	}
+	return entry.value, true, nil
+}
+
+func (b *MemoryBackend) Set(_ context.Context, key string, value []byte, ttl time.Duration) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	now := b.now()
+	if _, exists := b.entries[key]; !exists && len(b.entries) >= b.maxEntries {
+		b.evictLocked(now)
+	}
+	b.entries[key] = memoryEntry{value: append([]byte(nil), value...), expiresAt: now.Add(ttl)}
+	return nil
+}
+
+func (b *MemoryBackend) Generations(_ context.Context, names ...string) ([]int64, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	generations := make([]int64, len(names))
+	for i, name := range names {
+		generations[i] = b.generations[name]
+	}
+	return generations, nil
+}
+
+func (b *MemoryBackend) Bump(_ context.Context, name string) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.generations[name]++
+	return nil
+}
+
+// Len reports how many payloads are currently held, including expired ones
+// that have not been evicted yet.
+func (b *MemoryBackend) Len() int {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return len(b.entries)
+}
+
+// evictLocked drops expired entries and, when the backend is still full, an
+// arbitrary half of the remainder so bursts of unique keys cannot grow it
+// without bound.
+func (b *MemoryBackend) evictLocked(now time.Time) {
+	for key, entry := range b.entries {
+		if !now.Before(entry.expiresAt) {
+			delete(b.entries, key)
+		}
+	}
+	if len(b.entries) < b.maxEntries {
+		return
+	}
+	target := b.maxEntries / 2
+	for key := range b.entries {
+		if len(b.entries) <= target {
+			break
+		}
+		delete(b.entries, key)
+	}
+}
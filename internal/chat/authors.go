@@ -0,0 +1,216 @@
+package chat
+
+import (
+	"strings"
+	"sync"
+	"time"
+
+	"bitriver-live/internal/authz"
+	"bitriver-live/internal/models"
+)
+
+// Badges shown next to a chat author's name.
+const (
+	// BadgeBroadcaster marks the channel owner.
+	BadgeBroadcaster = "broadcaster"
+	// BadgeAdmin marks platform administrators.
+	BadgeAdmin = "admin"
+	// BadgeModerator marks users who may moderate the channel's chat.
+	BadgeModerator = "moderator"
+	// BadgeSubscriber marks users with an active subscription to the channel.
+	BadgeSubscriber = "subscriber"
+)
+
+// DeletedAuthorName is shown in place of authors whose account no longer
+// exists.
+const DeletedAuthorName = "Deleted user"
+
+const (
+	// authorCacheTTL bounds how long a cached author may lag behind a change
+	// the gateway was not told about, such as a subscription expiring.
+	authorCacheTTL = 5 * time.Minute
+	// maxCachedAuthorsPerChannel caps the per-channel cache; a full channel
+	// cache is dropped and rebuilt from the next messages.
+	maxCachedAuthorsPerChannel = 1024
+)
+
+// subscriberTierMonths are the cumulative subscribed months at which a
+// subscriber's badge steps up, lowest first.
+var subscriberTierMonths = []int{1, 3, 12}
+
+// SubscriberTier maps cumulative subscribed months to the badge tier they
+// earn: 1, 3, or 12, the highest threshold reached, or zero below the
+// first.
+func SubscriberTier(months int) int {
+	tier := 0
+	for _, threshold := range subscriberTierMonths {
+		if months >= threshold {
+			tier = threshold
+		}
+	}
+	return tier
+}
+
+// AuthorInfo is what the store knows about a chat author in a channel.
+// GlobalBadges holds the slugs of the platform-wide badges the author was
+// granted, such as staff or verified, in grant order. SubscriberMonths is the
+// author's cumulative months subscribed to the channel, across gaps.
+// ChannelModerator reports an unexpired moderator grant on the channel.
+type AuthorInfo struct {
+	ID               string
+	DisplayName      string
+	Roles            []string
+	AvatarURL        string
+	Subscriber       bool
+	SubscriberMonths int
+	GlobalBadges     []string
+	ChannelModerator bool
+}
+
+// AuthorStore resolves chat authors in bulk. Users that no longer exist are
+// left out of the returned map.
+type AuthorStore interface {
+	ChatAuthors(channelID string, userIDs []string) (map[string]AuthorInfo, error)
+}
+
+// Author is the presentation view of a message author attached to chat
+// history and outbound message events. Stored messages only carry the
+// author's ID. SubscriberTier accompanies the subscriber badge; see
+// SubscriberTier.
+type Author struct {
+	ID             string   `json:"id"`
+	DisplayName    string   `json:"displayName"`
+	AvatarURL      string   `json:"avatarUrl,omitempty"`
+	Badges         []string `json:"badges"`
+	SubscriberTier int      `json:"subscriberTier,omitempty"`
+}
+
+// subscribed reports whether the author carries the subscriber badge.
+func (a Author) subscribed() bool {
+	for _, badge := range a.Badges {
+		if badge == BadgeSubscriber {
+			return true
+		}
+	}
+	return false
+}
+
+// ComputeBadges returns the badges info earns in channel, ordered from most
+// to least prominent: the author's role in the channel, then their global
+// badges, then subscriber.
+func ComputeBadges(channel models.Channel, info AuthorInfo) []string {
+	badges := make([]string, 0, 2+len(info.GlobalBadges))
+	user := models.User{ID: info.ID, Roles: info.Roles}
+	switch {
+	case info.ID != "" && channel.OwnerID == info.ID:
+		badges = append(badges, BadgeBroadcaster)
+	case authz.Has(user, authz.PlatformManage):
+		badges = append(badges, BadgeAdmin)
+	case info.ChannelModerator || authz.CanModerateChannel(user, channel):
+		badges = append(badges, BadgeModerator)
+	}
+	badges = append(badges, info.GlobalBadges...)
+	if info.Subscriber {
+		badges = append(badges, BadgeSubscriber)
+	}
+	return badges
+}
+
+// NewAuthor builds the presentation view of info in channel.
+func NewAuthor(channel models.Channel, info AuthorInfo) Author {
+	author := Author{
+		ID:          info.ID,
+		DisplayName: info.DisplayName,
+		AvatarURL:   info.AvatarURL,
+		Badges:      ComputeBadges(channel, info),
+	}
+	if info.Subscriber {
+		author.SubscriberTier = SubscriberTier(info.SubscriberMonths)
+	}
+	return author
+}
+
+// DeletedAuthor stands in for an author whose account no longer exists.
+func DeletedAuthor(id string) Author {
+	return Author{ID: id, DisplayName: DeletedAuthorName, Badges: []string{}}
+}
+
+// ResolveAuthors looks up every distinct author in userIDs with a single
+// store call. Authors missing from the store resolve to DeletedAuthor.
+func ResolveAuthors(store AuthorStore, channel models.Channel, userIDs []string) (map[string]Author, error) {
+	ids := make([]string, 0, len(userIDs))
+	seen := make(map[string]struct{}, len(userIDs))
+	for _, id := range userIDs {
+		if _, ok := seen[id]; ok || strings.TrimSpace(id) == "" {
+			continue
+		}
+		seen[id] = struct{}{}
+		ids = append(ids, id)
+	}
+	authors := make(map[string]Author, len(ids))
+	if len(ids) == 0 {
+		return authors, nil
+	}
+	infos, err := store.ChatAuthors(channel.ID, ids)
+	if err != nil {
+		return nil, err
+	}
+	for _, id := range ids {
+		if info, ok := infos[id]; ok {
+			authors[id] = NewAuthor(channel, info)
+		} else {
+			authors[id] = DeletedAuthor(id)
+		}
+	}
+	return authors, nil
+}
+
+type cachedAuthor struct {
+	author  Author
+	expires time.Time
+}
+
+// authorCache keeps recently resolved authors per channel so live messages
+// do not cost a store lookup each.
+type authorCache struct {
+	mu       sync.Mutex
+	channels map[string]map[string]cachedAuthor
+}
+
+func (c *authorCache) get(channelID, userID string, now time.Time) (Author, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	entry, ok := c.channels[channelID][userID]
+	if !ok || !now.Before(entry.expires) {
+		return Author{}, false
+	}
+	return entry.author, true
+}
+
+func (c *authorCache) put(channelID string, author Author, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.channels == nil {
+		c.channels = make(map[string]map[string]cachedAuthor)
+	}
+	authors := c.channels[channelID]
+	if authors == nil || len(authors) >= maxCachedAuthorsPerChannel {
+		authors = make(map[string]cachedAuthor)
+		c.channels[channelID] = authors
+	}
+	authors[author.ID] = cachedAuthor{author: author, expires: now.Add(authorCacheTTL)}
+}
+
+func (c *authorCache) invalidateUser(userID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, authors := range c.channels {
+		delete(authors, userID)
+	}
+}
+
+func (c *authorCache) invalidateChannel(channelID string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	delete(c.channels, channelID)
+}
@@ -0,0 +1,163 @@
+package chat_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/chat"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+func TestComputeBadges(t *testing.T) {
+	channel := models.Channel{ID: "channel", OwnerID: "owner"}
+	cases := []struct {
+		name string
+		info chat.AuthorInfo
+		want []string
+	}{
+		{name: "viewer", info: chat.AuthorInfo{ID: "viewer"}, want: []string{}},
+		{name: "subscriber", info: chat.AuthorInfo{ID: "viewer", Subscriber: true}, want: []string{chat.BadgeSubscriber}},
+		{name: "owner", info: chat.AuthorInfo{ID: "owner", Roles: []string{"creator"}}, want: []string{chat.BadgeBroadcaster}},
+		{name: "owner who is an admin", info: chat.AuthorInfo{ID: "owner", Roles: []string{"admin"}}, want: []string{chat.BadgeBroadcaster}},
+		{name: "admin", info: chat.AuthorInfo{ID: "admin", Roles: []string{"admin"}}, want: []string{chat.BadgeAdmin}},
+		{name: "moderator", info: chat.AuthorInfo{ID: "mod", Roles: []string{"moderator"}}, want: []string{chat.BadgeModerator}},
+		{name: "subscribed moderator", info: chat.AuthorInfo{ID: "mod", Roles: []string{"moderator"}, Subscriber: true}, want: []string{chat.BadgeModerator, chat.BadgeSubscriber}},
+		{name: "creator elsewhere", info: chat.AuthorInfo{ID: "creator", Roles: []string{"creator"}}, want: []string{}},
+		{name: "editor", info: chat.AuthorInfo{ID: "editor", Roles: []string{"editor"}}, want: []string{}},
+		{name: "verified viewer", info: chat.AuthorInfo{ID: "viewer", GlobalBadges: []string{"verified"}}, want: []string{"verified"}},
+		{name: "staff moderator subscriber", info: chat.AuthorInfo{ID: "mod", Roles: []string{"moderator"}, Subscriber: true, GlobalBadges: []string{"staff", "founder"}}, want: []string{chat.BadgeModerator, "staff", "founder", chat.BadgeSubscriber}},
+	}
+	for _, tc := range cases {
+		if got := chat.ComputeBadges(channel, tc.info); !reflect.DeepEqual(got, tc.want) {
+			t.Fatalf("%s: expected %v, got %v", tc.name, tc.want, got)
+		}
+	}
+}
+
+func TestSubscriberTiers(t *testing.T) {
+	for months, want := range map[int]int{0: 0, 1: 1, 2: 1, 3: 3, 11: 3, 12: 12, 40: 12} {
+		if got := chat.SubscriberTier(months); got != want {
+			t.Fatalf("SubscriberTier(%d) = %d, want %d", months, got, want)
+		}
+	}
+
+	channel := models.Channel{ID: "channel", OwnerID: "owner"}
+	veteran := chat.NewAuthor(channel, chat.AuthorInfo{ID: "viewer", DisplayName: "Viewer", Subscriber: true, SubscriberMonths: 14})
+	if veteran.SubscriberTier != 12 {
+		t.Fatalf("expected a 12-month tier, got %+v", veteran)
+	}
+	lapsed := chat.NewAuthor(channel, chat.AuthorInfo{ID: "viewer", DisplayName: "Viewer", SubscriberMonths: 14})
+	if lapsed.SubscriberTier != 0 {
+		t.Fatalf("expected no tier without the subscriber badge, got %+v", lapsed)
+	}
+}
+
+// countingAuthorStore counts ChatAuthors calls and can fail them.
+type countingAuthorStore struct {
+	infos map[string]chat.AuthorInfo
+	err   error
+	calls int
+	ids   []string
+}
+
+func (s *countingAuthorStore) ChatAuthors(channelID string, userIDs []string) (map[string]chat.AuthorInfo, error) {
+	s.calls++
+	s.ids = append([]string(nil), userIDs...)
+	if s.err != nil {
+		return nil, s.err
+	}
+	return s.infos, nil
+}
+
+func TestResolveAuthorsRendersDeletedUsers(t *testing.T) {
+	channel := models.Channel{ID: "channel", OwnerID: "owner"}
+	store := &countingAuthorStore{infos: map[string]chat.AuthorInfo{
+		"owner": {ID: "owner", DisplayName: "Owner", AvatarURL: "https://cdn.example.com/owner.png"},
+	}}
+	authors, err := chat.ResolveAuthors(store, channel, []string{"owner", "gone", "owner", "gone"})
+	if err != nil {
+		t.Fatalf("ResolveAuthors: %v", err)
+	}
+	if store.calls != 1 || !reflect.DeepEqual(store.ids, []string{"owner", "gone"}) {
+		t.Fatalf("expected one lookup of the distinct authors, got %d calls for %v", store.calls, store.ids)
+	}
+	want := chat.Author{ID: "owner", DisplayName: "Owner", AvatarURL: "https://cdn.example.com/owner.png", Badges: []string{chat.BadgeBroadcaster}}
+	if !reflect.DeepEqual(authors["owner"], want) {
+		t.Fatalf("expected %+v, got %+v", want, authors["owner"])
+	}
+	if deleted := authors["gone"]; deleted.DisplayName != chat.DeletedAuthorName || deleted.AvatarURL != "" || len(deleted.Badges) != 0 || deleted.Badges == nil {
+		t.Fatalf("expected a deleted user without badges, got %+v", deleted)
+	}
+
+	store.err = errors.New("database down")
+	if _, err := chat.ResolveAuthors(store, channel, []string{"owner"}); err == nil {
+		t.Fatal("expected lookup errors to be returned")
+	}
+	calls := store.calls
+	if authors, err := chat.ResolveAuthors(store, channel, nil); err != nil || len(authors) != 0 || store.calls != calls {
+		t.Fatalf("expected no lookup without authors, got %v (err %v)", authors, err)
+	}
+}
+
+func TestGatewayAuthorCacheInvalidation(t *testing.T) {
+	store := newTestStorage(t)
+	owner := mustCreateUser(t, store, storage.CreateUserParams{DisplayName: "owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	viewer := mustCreateUser(t, store, storage.CreateUserParams{DisplayName: "viewer", Email: "viewer@example.com"})
+	channel := mustCreateChannel(t, store, owner.ID, "Main")
+
+	queue := chat.NewMemoryQueue(8)
+	sub := queue.Subscribe()
+	defer sub.Close()
+	gateway := chat.NewGateway(chat.GatewayConfig{Queue: queue, Store: store})
+
+	send := func() chat.MessageEvent {
+		t.Helper()
+		message, err := gateway.CreateMessage(context.Background(), viewer, channel.ID, "hello", "")
+		if err != nil {
+			t.Fatalf("CreateMessage: %v", err)
+		}
+		if message.Author == nil {
+			t.Fatal("expected the message to carry its author")
+		}
+		select {
+		case evt := <-sub.Events():
+			if evt.Message == nil || evt.Message.Author != nil {
+				t.Fatalf("expected the queued message to stay ID-only, got %+v", evt.Message)
+			}
+		case <-time.After(time.Second):
+			t.Fatal("expected the message to be queued")
+		}
+		return message
+	}
+
+	first := send()
+	if first.Author.DisplayName != "viewer" || len(first.Author.Badges) != 0 {
+		t.Fatalf("expected a plain viewer, got %+v", first.Author)
+	}
+
+	roles := []string{"moderator"}
+	if _, err := store.UpdateUser(viewer.ID, storage.UserUpdate{Roles: &roles}); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	if cached := send(); len(cached.Author.Badges) != 0 {
+		t.Fatalf("expected the cached author until invalidated, got %+v", cached.Author)
+	}
+
+	gateway.InvalidateAuthor(viewer.ID)
+	if refreshed := send(); !reflect.DeepEqual(refreshed.Author.Badges, []string{chat.BadgeModerator}) {
+		t.Fatalf("expected the moderator badge after invalidation, got %+v", refreshed.Author)
+	}
+
+	name := "renamed"
+	if _, err := store.UpdateUser(viewer.ID, storage.UserUpdate{DisplayName: &name}); err != nil {
+		t.Fatalf("UpdateUser: %v", err)
+	}
+	gateway.InvalidateChannelAuthors(channel.ID)
+	if renamed := send(); renamed.Author.DisplayName != "renamed" {
+		t.Fatalf("expected the new name after invalidating the channel, got %+v", renamed.Author)
+	}
+}
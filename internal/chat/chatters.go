@@ -0,0 +1,48 @@
+package chat
+
+import "sync"
+
+// maxCachedChattersPerChannel caps the per-channel set of known chatters; a
+// full set is dropped and rebuilt from the next messages.
+const maxCachedChattersPerChannel = 4096
+
+// ChatterStore answers whether a user has chatted in a channel before. The
+// lookup runs on the live message path, so implementations must answer from
+// an index rather than scanning the channel's transcript.
+type ChatterStore interface {
+	HasChatted(channelID, userID string) (bool, error)
+}
+
+// chatterCache remembers users known to have chatted in each channel so only
+// their first message in a while costs a store lookup.
+type chatterCache struct {
+	mu       sync.Mutex
+	channels map[string]map[string]struct{}
+}
+
+func (c *chatterCache) known(channelID, userID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	_, ok := c.channels[channelID][userID]
+	return ok
+}
+
+// add records userID as a chatter in channelID and reports whether it was
+// not already known, so concurrent first messages agree on a single winner.
+func (c *chatterCache) add(channelID, userID string) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.channels == nil {
+		c.channels = make(map[string]map[string]struct{})
+	}
+	chatters := c.channels[channelID]
+	if _, ok := chatters[userID]; ok {
+		return false
+	}
+	if chatters == nil || len(chatters) >= maxCachedChattersPerChannel {
+		chatters = make(map[string]struct{})
+		c.channels[channelID] = chatters
+	}
+	chatters[userID] = struct{}{}
+	return true
+}
@@ -0,0 +1,95 @@
+package chat_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/chat"
+	"bitriver-live/internal/storage"
+)
+
+// countingChatterStore counts chat history lookups and can fail them.
+type countingChatterStore struct {
+	*storage.Storage
+	err   error
+	calls int
+}
+
+func (s *countingChatterStore) HasChatted(channelID, userID string) (bool, error) {
+	s.calls++
+	if s.err != nil {
+		return false, s.err
+	}
+	return s.Storage.HasChatted(channelID, userID)
+}
+
+func TestGatewayFlagsFirstMessages(t *testing.T) {
+	backing := newTestStorage(t)
+	owner := mustCreateUser(t, backing, storage.CreateUserParams{DisplayName: "owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	regular := mustCreateUser(t, backing, storage.CreateUserParams{DisplayName: "regular", Email: "regular@example.com"})
+	newcomer := mustCreateUser(t, backing, storage.CreateUserParams{DisplayName: "newcomer", Email: "newcomer@example.com"})
+	channel := mustCreateChannel(t, backing, owner.ID, "Main")
+	other := mustCreateChannel(t, backing, owner.ID, "Other")
+	if _, err := backing.CreateChatMessage(channel.ID, regular.ID, "been here before", ""); err != nil {
+		t.Fatalf("CreateChatMessage: %v", err)
+	}
+
+	store := &countingChatterStore{Storage: backing}
+	queue := chat.NewMemoryQueue(16)
+	sub := queue.Subscribe()
+	defer sub.Close()
+	gateway := chat.NewGateway(chat.GatewayConfig{Queue: queue, Store: store})
+
+	send := func(channelID string, authorID string) (chat.MessageEvent, chat.MessageEvent) {
+		t.Helper()
+		author, _ := backing.GetUser(authorID)
+		message, err := gateway.CreateMessage(context.Background(), author, channelID, "hello", "")
+		if err != nil {
+			t.Fatalf("CreateMessage: %v", err)
+		}
+		select {
+		case evt := <-sub.Events():
+			return message, *evt.Message
+		case <-time.After(time.Second):
+			t.Fatal("expected the message to be queued")
+		}
+		return chat.MessageEvent{}, chat.MessageEvent{}
+	}
+
+	first, queued := send(channel.ID, newcomer.ID)
+	if !first.IsFirstMessage || !queued.IsFirstMessage {
+		t.Fatalf("expected the newcomer's first message flagged on both events, got %v and %v", first.IsFirstMessage, queued.IsFirstMessage)
+	}
+	if store.calls != 1 {
+		t.Fatalf("expected one history lookup, got %d", store.calls)
+	}
+	// The first message has not been persisted yet; the gateway must not
+	// greet the newcomer again while it is in flight.
+	if again, _ := send(channel.ID, newcomer.ID); again.IsFirstMessage {
+		t.Fatal("expected the second message to be a returning one")
+	}
+	if store.calls != 1 {
+		t.Fatalf("expected known chatters to skip the store, got %d lookups", store.calls)
+	}
+
+	if returning, queued := send(channel.ID, regular.ID); returning.IsFirstMessage || queued.IsFirstMessage {
+		t.Fatal("expected a user with stored history to be returning")
+	}
+	if elsewhere, _ := send(other.ID, regular.ID); !elsewhere.IsFirstMessage {
+		t.Fatal("expected first messages to be tracked per channel")
+	}
+	if store.calls != 3 {
+		t.Fatalf("expected one lookup per new chatter and channel, got %d", store.calls)
+	}
+
+	store.err = errors.New("database down")
+	if failed, _ := send(channel.ID, owner.ID); failed.IsFirstMessage {
+		t.Fatal("expected lookup failures to fall back to a returning chatter")
+	}
+	store.err = nil
+	if retried, _ := send(channel.ID, owner.ID); !retried.IsFirstMessage {
+		t.Fatal("expected failed lookups not to be cached")
+	}
+}
@@ -0,0 +1,90 @@
+package chat
+
+import (
+	"errors"
+	"strings"
+	"sync"
+	"time"
+)
+
+// ClientMessageIDWindow is how long a client message ID identifies the
+// message it was first sent with. A resubmission with the same channel,
+// author, and client message ID inside the window returns the original
+// message; after it the ID may be reused.
+const ClientMessageIDWindow = 10 * time.Minute
+
+// maxPendingClientMessageIDs caps the gateway's dedupe cache; expired
+// entries are swept once it fills up.
+const maxPendingClientMessageIDs = 4096
+
+// ErrInvalidClientMessageID reports a client message ID that is not a UUID.
+var ErrInvalidClientMessageID = errors.New("clientMessageId must be a UUID")
+
+// NormalizeClientMessageID validates a client-generated message ID and returns
+// it in canonical lower-case form. An empty ID is allowed and returned as is.
+func NormalizeClientMessageID(raw string) (string, error) {
+	id := strings.ToLower(strings.TrimSpace(raw))
+	if id == "" {
+		return "", nil
+	}
+	if len(id) != 36 {
+		return "", ErrInvalidClientMessageID
+	}
+	for i, r := range id {
+		switch i {
+		case 8, 13, 18, 23:
+			if r != '-' {
+				return "", ErrInvalidClientMessageID
+			}
+		default:
+			if !(r >= '0' && r <= '9' || r >= 'a' && r <= 'f') {
+				return "", ErrInvalidClientMessageID
+			}
+		}
+	}
+	return id, nil
+}
+
+// ClientMessageKey identifies a client message ID within the scope it must be
+// unique in.
+func ClientMessageKey(channelID, userID, clientMessageID string) string {
+	return channelID + "\x00" + userID + "\x00" + clientMessageID
+}
+
+// ClientMessageIDActive reports whether a message created at createdAt still
+// reserves its client message ID at now.
+func ClientMessageIDActive(createdAt, now time.Time) bool {
+	return now.Sub(createdAt) < ClientMessageIDWindow
+}
+
+// clientMessageCache remembers the messages the gateway accepted with a
+// client message ID so retried submissions are answered with the original
+// instead of being broadcast and queued again.
+type clientMessageCache struct {
+	mu       sync.Mutex
+	messages map[string]MessageEvent
+}
+
+// claim records message under its client message ID unless a message sent
+// with the same ID is still active, in which case that one is returned with
+// false.
+func (c *clientMessageCache) claim(message MessageEvent, now time.Time) (MessageEvent, bool) {
+	key := ClientMessageKey(message.ChannelID, message.UserID, message.ClientMessageID)
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if existing, ok := c.messages[key]; ok && ClientMessageIDActive(existing.CreatedAt, now) {
+		return existing, false
+	}
+	if c.messages == nil {
+		c.messages = make(map[string]MessageEvent)
+	}
+	if len(c.messages) >= maxPendingClientMessageIDs {
+		for k, cached := range c.messages {
+			if !ClientMessageIDActive(cached.CreatedAt, now) {
+				delete(c.messages, k)
+			}
+		}
+	}
+	c.messages[key] = message
+	return message, true
+}
@@ -0,0 +1,116 @@
+package chat_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/chat"
+	"bitriver-live/internal/storage"
+)
+
+type recordingQueue struct {
+	mu     sync.Mutex
+	events []chat.Event
+}
+
+func (q *recordingQueue) Publish(_ context.Context, event chat.Event) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	q.events = append(q.events, event)
+	return nil
+}
+
+func (q *recordingQueue) Subscribe() chat.Subscription {
+	return nil
+}
+
+func (q *recordingQueue) published() []chat.Event {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	return append([]chat.Event(nil), q.events...)
+}
+
+func TestNormalizeClientMessageID(t *testing.T) {
+	cases := []struct {
+		raw  string
+		want string
+		err  bool
+	}{
+		{raw: "", want: ""},
+		{raw: " 6F1C2A4E-8B7D-4C3A-9E21-0D5B6A7C8E9F ", want: "6f1c2a4e-8b7d-4c3a-9e21-0d5b6a7c8e9f"},
+		{raw: "6f1c2a4e8b7d4c3a9e210d5b6a7c8e9f", err: true},
+		{raw: "6f1c2a4e-8b7d-4c3a-9e21-0d5b6a7c8e9g", err: true},
+		{raw: "6f1c2a4e-8b7d-4c3a-9e210-d5b6a7c8e9f", err: true},
+		{raw: "hello", err: true},
+	}
+	for _, tc := range cases {
+		got, err := chat.NormalizeClientMessageID(tc.raw)
+		if tc.err {
+			if !errors.Is(err, chat.ErrInvalidClientMessageID) {
+				t.Errorf("NormalizeClientMessageID(%q): expected an error, got %q", tc.raw, got)
+			}
+			continue
+		}
+		if err != nil || got != tc.want {
+			t.Errorf("NormalizeClientMessageID(%q) = %q, %v; want %q", tc.raw, got, err, tc.want)
+		}
+	}
+}
+
+func TestGatewayDeduplicatesClientMessageIDs(t *testing.T) {
+	store := newTestStorage(t)
+	owner := mustCreateUser(t, store, storage.CreateUserParams{DisplayName: "owner", Email: "owner@example.com"})
+	viewer := mustCreateUser(t, store, storage.CreateUserParams{DisplayName: "viewer", Email: "viewer@example.com"})
+	channel := mustCreateChannel(t, store, owner.ID, "Main")
+
+	queue := &recordingQueue{}
+	gateway := chat.NewGateway(chat.GatewayConfig{Queue: queue, Store: store})
+	ctx := context.Background()
+	const clientID = "6f1c2a4e-8b7d-4c3a-9e21-0d5b6a7c8e9f"
+
+	if _, err := gateway.CreateMessage(ctx, viewer, channel.ID, "hello", "nope"); !errors.Is(err, chat.ErrInvalidClientMessageID) {
+		t.Fatalf("expected an invalid client id to be rejected, got %v", err)
+	}
+
+	original, err := gateway.CreateMessage(ctx, viewer, channel.ID, "hello", clientID)
+	if err != nil {
+		t.Fatalf("CreateMessage: %v", err)
+	}
+	if original.ClientMessageID != clientID {
+		t.Fatalf("expected the client id on the message, got %q", original.ClientMessageID)
+	}
+	retried, err := gateway.CreateMessage(ctx, viewer, channel.ID, "hello", clientID)
+	if err != nil {
+		t.Fatalf("CreateMessage retry: %v", err)
+	}
+	if retried.ID != original.ID || retried.Author == nil {
+		t.Fatalf("expected the retry to return the original with its author, got %+v", retried)
+	}
+	if _, err := gateway.CreateMessage(ctx, owner, channel.ID, "hi", clientID); err != nil {
+		t.Fatalf("CreateMessage for another user: %v", err)
+	}
+	if _, err := gateway.CreateMessage(ctx, viewer, channel.ID, "again", ""); err != nil {
+		t.Fatalf("CreateMessage without client id: %v", err)
+	}
+
+	published := queue.published()
+	if len(published) != 3 {
+		t.Fatalf("expected 3 queued messages, got %d", len(published))
+	}
+	if queued := published[0].Message; queued.ClientMessageID != clientID || queued.Author != nil {
+		t.Fatalf("expected the queued event to carry the client id without the author, got %+v", queued)
+	}
+}
+
+func TestClientMessageIDActive(t *testing.T) {
+	created := time.Date(2026, 1, 1, 12, 0, 0, 0, time.UTC)
+	if !chat.ClientMessageIDActive(created, created.Add(chat.ClientMessageIDWindow-time.Second)) {
+		t.Fatal("expected the ID to be reserved inside the window")
+	}
+	if chat.ClientMessageIDActive(created, created.Add(chat.ClientMessageIDWindow)) {
+		t.Fatal("expected the ID to be released once the window has passed")
+	}
+}
@@ -0,0 +1,428 @@
+package chat
+
+import (
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"golang.org/x/text/unicode/norm"
+
+	"bitriver-live/internal/authz"
+	"bitriver-live/internal/models"
+)
+
+const (
+	// maxCombiningMarks caps the combining marks kept on one base character:
+	// enough for scripts that stack diacritics, too few for zalgo text.
+	maxCombiningMarks = 3
+	// minCapsLetters is how many cased letters a message needs before the
+	// caps rule applies, so short shouts like "GG" pass untouched.
+	minCapsLetters = 8
+	// maxHostLength and maxLabelLength are the DNS limits on the ASCII form
+	// of a host and of each of its labels.
+	maxHostLength  = 253
+	maxLabelLength = 63
+)
+
+// ErrLinksRestricted reports a message with links sent to a channel that only
+// accepts links from followers and subscribers.
+var ErrLinksRestricted = errors.New("only followers and subscribers may post links in this channel")
+
+// ErrSubscribersOnly reports a message sent to a channel with subscriber-only
+// chat by a user who neither subscribes to it nor moderates it.
+var ErrSubscribersOnly = errors.New("only subscribers may chat in this channel")
+
+// ErrorCodeSubscribersOnly is the code clients receive with
+// ErrSubscribersOnly, so they can offer a subscription instead of showing a
+// generic failure.
+const ErrorCodeSubscribersOnly = "subscribers_only"
+
+// ErrorCode returns the machine-readable code for chat errors that clients
+// handle specially, or an empty string.
+func ErrorCode(err error) string {
+	if errors.Is(err, ErrSubscribersOnly) {
+		return ErrorCodeSubscribersOnly
+	}
+	return ""
+}
+
+// MessageURL is a link found in a chat message.
+type MessageURL struct {
+	// Text is the link as it appears in the message content.
+	Text string `json:"text"`
+	// URL is the absolute link, defaulting to https for bare domains.
+	URL string `json:"url"`
+	// Host is the link's host in ASCII form. Internationalized hosts are
+	// punycode-encoded so look-alike characters cannot disguise them.
+	Host string `json:"host"`
+}
+
+// ProcessedContent is a chat message's content as it is stored, along with
+// the links found in it.
+type ProcessedContent struct {
+	Content string
+	URLs    []MessageURL
+}
+
+// ProcessContent prepares raw chat input for storage: it drops control,
+// zero-width, and other invisible characters, normalizes the text to NFC,
+// trims stacked combining marks, and lowercases messages whose letters are
+// more than maxCapsPercent capitals (zero disables the rule). Links keep
+// their case. The processed content replaces the original entirely.
+func ProcessContent(raw string, maxCapsPercent int) ProcessedContent {
+	content := strings.TrimSpace(limitCombiningMarks(norm.NFC.String(stripInvisible(raw))))
+	spans := findLinks(content)
+	if exceedsCaps(content, spans, maxCapsPercent) {
+		content = lowercaseOutside(content, spans)
+		spans = findLinks(content)
+	}
+	return ProcessedContent{Content: content, URLs: spanURLs(spans)}
+}
+
+// ExtractURLs returns the links in already processed content, such as a
+// stored message.
+func ExtractURLs(content string) []MessageURL {
+	return spanURLs(findLinks(content))
+}
+
+func spanURLs(spans []linkSpan) []MessageURL {
+	if len(spans) == 0 {
+		return nil
+	}
+	urls := make([]MessageURL, 0, len(spans))
+	for _, span := range spans {
+		urls = append(urls, span.url)
+	}
+	return urls
+}
+
+// LinksAllowed reports whether user may post links in channel. Channels that
+// restrict links accept them from followers, subscribers, and the people who
+// moderate the channel, including holders of a channel moderator grant.
+func LinksAllowed(channel models.Channel, user models.User, following, subscribed, moderator bool) bool {
+	if !channel.ChatRestrictLinks {
+		return true
+	}
+	return following || subscribed || moderator || authz.CanModerateChannel(user, channel)
+}
+
+// ChatAllowed reports whether user may chat in channel at all. Channels with
+// subscriber-only chat accept messages from subscribers, whether or not the
+// subscription was cancelled before it expired, and from the people who
+// moderate the channel, including holders of a channel moderator grant.
+func ChatAllowed(channel models.Channel, user models.User, subscribed, moderator bool) bool {
+	if !channel.ChatSubscribersOnly {
+		return true
+	}
+	return subscribed || moderator || authz.CanModerateChannel(user, channel)
+}
+
+// NormalizeMaxCapsPercent validates a channel's caps rule, which is either
+// zero (off) or a percentage between 1 and 100.
+func NormalizeMaxCapsPercent(value int) (int, error) {
+	if value < 0 || value > 100 {
+		return 0, errors.New("chatMaxCapsPercent must be between 0 and 100")
+	}
+	return value, nil
+}
+
+// MaxWelcomeMessageLength caps a channel's chat welcome message, in
+// characters.
+const MaxWelcomeMessageLength = 500
+
+// NormalizeWelcomeMessage prepares a channel's chat welcome message the way
+// ProcessContent prepares messages, without the caps rule. Empty clears it.
+func NormalizeWelcomeMessage(raw string) (string, error) {
+	message := ProcessContent(raw, 0).Content
+	if utf8.RuneCountInString(message) > MaxWelcomeMessageLength {
+		return "", fmt.Errorf("chatWelcomeMessage exceeds %d characters", MaxWelcomeMessageLength)
+	}
+	return message, nil
+}
+
+// stripInvisible removes control and format characters, maps line breaks
+// and tabs to spaces, and keeps zero-width joiners only inside emoji
+// sequences.
+func stripInvisible(raw string) string {
+	var b strings.Builder
+	b.Grow(len(raw))
+	var prev rune
+	for i, r := range raw {
+		switch {
+		case r == '\t' || r == '\n' || r == '\r' || r == '\u2028' || r == '\u2029':
+			r = ' '
+		case r == '\u200d':
+			next, _ := utf8.DecodeRuneInString(raw[i+utf8.RuneLen(r):])
+			if !isEmojiRune(prev) || !isEmojiRune(next) {
+				continue
+			}
+		case r == utf8.RuneError, unicode.Is(unicode.Cc, r), unicode.Is(unicode.Cf, r), isInvisibleFiller(r):
+			continue
+		}
+		b.WriteRune(r)
+		prev = r
+	}
+	return b.String()
+}
+
+// isEmojiRune approximates whether r may sit on either side of a zero-width
+// joiner in an emoji sequence, including variation selectors and skin tones.
+func isEmojiRune(r rune) bool {
+	return r == '\ufe0f' || unicode.In(r, unicode.So, unicode.Sk)
+}
+
+// isInvisibleFiller reports characters that render blank without being
+// whitespace, a common way to post messages that look empty.
+func isInvisibleFiller(r rune) bool {
+	switch r {
+	case '\u034f', '\u115f', '\u1160', '\u2800', '\u3164', '\uffa0':
+		return true
+	}
+	return false
+}
+
+// limitCombiningMarks drops combining marks beyond maxCombiningMarks in a row.
+func limitCombiningMarks(s string) string {
+	var b strings.Builder
+	b.Grow(len(s))
+	marks := 0
+	for _, r := range s {
+		if unicode.In(r, unicode.Mn, unicode.Me) {
+			marks++
+			if marks > maxCombiningMarks {
+				continue
+			}
+		} else {
+			marks = 0
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// exceedsCaps reports whether the cased letters outside links are more than
+// maxPercent capitals.
+func exceedsCaps(content string, links []linkSpan, maxPercent int) bool {
+	if maxPercent <= 0 || maxPercent >= 100 {
+		return false
+	}
+	upper, cased := 0, 0
+	forEachOutside(content, links, func(text string) {
+		for _, r := range text {
+			switch {
+			case unicode.IsUpper(r):
+				upper++
+				cased++
+			case unicode.IsLower(r):
+				cased++
+			}
+		}
+	})
+	return cased >= minCapsLetters && upper*100 > maxPercent*cased
+}
+
+// lowercaseOutside lowercases content except for the links in it, whose
+// paths may be case-sensitive.
+func lowercaseOutside(content string, links []linkSpan) string {
+	var b strings.Builder
+	b.Grow(len(content))
+	last := 0
+	for _, link := range links {
+		b.WriteString(strings.ToLower(content[last:link.start]))
+		b.WriteString(content[link.start:link.end])
+		last = link.end
+	}
+	b.WriteString(strings.ToLower(content[last:]))
+	return b.String()
+}
+
+func forEachOutside(content string, links []linkSpan, fn func(string)) {
+	last := 0
+	for _, link := range links {
+		fn(content[last:link.start])
+		last = link.end
+	}
+	fn(content[last:])
+}
+
+// linkSpan locates a link within message content by byte offsets.
+type linkSpan struct {
+	start, end int
+	url        MessageURL
+}
+
+const (
+	linkOpeners = "([{<\"'"
+	linkClosers = ".,;:!?'\")]}>"
+)
+
+// findLinks returns the links in content in order. Links are whitespace
+// separated words with an http(s) scheme or the shape of a domain name;
+// wrapping brackets, quotes, and trailing punctuation are not part of them.
+func findLinks(content string) []linkSpan {
+	var spans []linkSpan
+	start := -1
+	for i, r := range content + " " {
+		if !unicode.IsSpace(r) {
+			if start < 0 {
+				start = i
+			}
+			continue
+		}
+		if start < 0 {
+			continue
+		}
+		if span, ok := linkInWord(content, start, i); ok {
+			spans = append(spans, span)
+		}
+		start = -1
+	}
+	return spans
+}
+
+func linkInWord(content string, start, end int) (linkSpan, bool) {
+	for start < end && strings.IndexByte(linkOpeners, content[start]) >= 0 {
+		start++
+	}
+	for end > start {
+		last := content[end-1]
+		if strings.IndexByte(linkClosers, last) < 0 {
+			break
+		}
+		word := content[start:end]
+		if last == ')' && strings.Count(word, "(") >= strings.Count(word, ")") {
+			// Keep the closing parenthesis of links like /wiki/Go_(language).
+			break
+		}
+		end--
+	}
+	if start >= end {
+		return linkSpan{}, false
+	}
+	url, ok := parseLink(content[start:end])
+	if !ok {
+		return linkSpan{}, false
+	}
+	return linkSpan{start: start, end: end, url: url}, true
+}
+
+// parseLink recognizes text as an http(s) URL or a bare domain, optionally
+// with a port and path, and returns it with its host in ASCII form.
+func parseLink(text string) (MessageURL, bool) {
+	scheme, rest := "https", text
+	explicit := false
+	lower := strings.ToLower(text)
+	for _, candidate := range []string{"https", "http"} {
+		if strings.HasPrefix(lower, candidate+"://") {
+			scheme, rest, explicit = candidate, text[len(candidate)+len("://"):], true
+			break
+		}
+	}
+	if !explicit && strings.Contains(text, "://") {
+		return MessageURL{}, false
+	}
+	authority, tail := rest, ""
+	if cut := strings.IndexAny(rest, "/?#"); cut >= 0 {
+		authority, tail = rest[:cut], rest[cut:]
+	}
+	if at := strings.LastIndexByte(authority, '@'); at >= 0 {
+		if !explicit {
+			// Bare user@host words are e-mail addresses, not links.
+			return MessageURL{}, false
+		}
+		authority = authority[at+1:]
+	}
+	host, port := authority, ""
+	if colon := strings.LastIndexByte(authority, ':'); colon >= 0 {
+		host, port = authority[:colon], authority[colon+1:]
+		if !validPort(port) {
+			return MessageURL{}, false
+		}
+	}
+	asciiHost, ok := asciiHostname(host, explicit)
+	if !ok {
+		return MessageURL{}, false
+	}
+	url := scheme + "://" + asciiHost
+	if port != "" {
+		url += ":" + port
+	}
+	return MessageURL{Text: text, URL: url + tail, Host: asciiHost}, true
+}
+
+func validPort(port string) bool {
+	if port == "" || len(port) > 5 {
+		return false
+	}
+	for _, r := range port {
+		if r < '0' || r > '9' {
+			return false
+		}
+	}
+	return true
+}
+
+// asciiHostname lower-cases host and punycode-encodes its internationalized
+// labels. Only explicit http(s) links may use IPv4 addresses; bare words need
+// an alphabetic or punycode top-level domain to count as domains.
+func asciiHostname(host string, explicit bool) (string, bool) {
+	host = strings.NewReplacer("\u3002", ".", "\uff0e", ".", "\uff61", ".").Replace(host)
+	host = strings.TrimSuffix(strings.ToLower(host), ".")
+	if explicit && strings.Count(host, ".") == 3 {
+		if ip := net.ParseIP(host); ip != nil && ip.To4() != nil {
+			return host, true
+		}
+	}
+	labels := strings.Split(host, ".")
+	if len(labels) < 2 {
+		return "", false
+	}
+	for i, label := range labels {
+		if !validLabel(label) {
+			return "", false
+		}
+		ascii, err := punycodeLabel(label)
+		if err != nil || len(ascii) > maxLabelLength {
+			return "", false
+		}
+		labels[i] = ascii
+	}
+	if !validTopLevelDomain(labels[len(labels)-1]) {
+		return "", false
+	}
+	ascii := strings.Join(labels, ".")
+	if len(ascii) > maxHostLength {
+		return "", false
+	}
+	return ascii, true
+}
+
+func validLabel(label string) bool {
+	if label == "" || strings.HasPrefix(label, "-") || strings.HasSuffix(label, "-") {
+		return false
+	}
+	for _, r := range label {
+		if r != '-' && !unicode.IsLetter(r) && !unicode.IsDigit(r) && !unicode.In(r, unicode.Mn, unicode.Mc) {
+			return false
+		}
+	}
+	return true
+}
+
+func validTopLevelDomain(label string) bool {
+	if strings.HasPrefix(label, punycodeACEPrefix) {
+		return len(label) > len(punycodeACEPrefix)
+	}
+	if len(label) < 2 {
+		return false
+	}
+	for _, r := range label {
+		if r < 'a' || r > 'z' {
+			return false
+		}
+	}
+	return true
+}
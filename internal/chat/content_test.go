@@ -0,0 +1,200 @@
+package chat_test
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/chat"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+func TestProcessContentNormalizes(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want string
+	}{
+		{name: "composes to NFC", in: "cafe\u0301", want: "café"},
+		{name: "strips zero-width characters", in: "a\u200bb\u200cc\u2060d\ufeff", want: "abcd"},
+		{name: "strips bidi overrides", in: "\u202egnp.exe", want: "gnp.exe"},
+		{name: "strips control characters", in: "ring\x07 the\x00 bell", want: "ring the bell"},
+		{name: "maps line breaks and tabs to spaces", in: "one\ntwo\tthree\u2028four", want: "one two three four"},
+		{name: "trims after stripping", in: "\u200b  hi  \u200b", want: "hi"},
+		{name: "keeps emoji joiners", in: "\U0001F469\u200d\U0001F4BB", want: "\U0001F469\u200d\U0001F4BB"},
+		{name: "drops stray joiners", in: "a\u200db", want: "ab"},
+		{name: "caps stacked marks", in: "Z" + strings.Repeat("\u0336", 10) + "!", want: "Z\u0336\u0336\u0336!"},
+		{name: "keeps ordinary diacritics", in: "Tiếng Việt", want: "Tiếng Việt"},
+		{name: "drops invisible fillers", in: "\u3164\u2800\u115f", want: ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := chat.ProcessContent(tc.in, 0).Content; got != tc.want {
+				t.Fatalf("ProcessContent(%q) = %q, want %q", tc.in, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestProcessContentCaps(t *testing.T) {
+	cases := []struct {
+		name    string
+		in      string
+		percent int
+		want    string
+	}{
+		{name: "rule off", in: "THIS IS VERY LOUD", percent: 0, want: "THIS IS VERY LOUD"},
+		{name: "over the limit", in: "THIS IS VERY LOUD", percent: 50, want: "this is very loud"},
+		{name: "under the limit", in: "Hello There My Friends", percent: 50, want: "Hello There My Friends"},
+		{name: "short shouts pass", in: "GG WP", percent: 50, want: "GG WP"},
+		{name: "non-letters are ignored", in: "LOL!!! 1234567890", percent: 50, want: "LOL!!! 1234567890"},
+		{name: "non-Latin letters", in: "ПРИВЕТ ВСЕМ", percent: 50, want: "привет всем"},
+		{name: "links keep their case", in: "WATCH THIS NOW https://Example.com/AbC", percent: 50, want: "watch this now https://Example.com/AbC"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := chat.ProcessContent(tc.in, tc.percent).Content; got != tc.want {
+				t.Fatalf("ProcessContent(%q, %d) = %q, want %q", tc.in, tc.percent, got, tc.want)
+			}
+		})
+	}
+}
+
+func TestProcessContentExtractsLinks(t *testing.T) {
+	cases := []struct {
+		name string
+		in   string
+		want []chat.MessageURL
+	}{
+		{name: "scheme", in: "see https://example.com/a?b=1#c", want: []chat.MessageURL{{Text: "https://example.com/a?b=1#c", URL: "https://example.com/a?b=1#c", Host: "example.com"}}},
+		{name: "bare domain", in: "go to Example.COM", want: []chat.MessageURL{{Text: "Example.COM", URL: "https://example.com", Host: "example.com"}}},
+		{name: "trailing punctuation", in: "try www.example.org/path!", want: []chat.MessageURL{{Text: "www.example.org/path", URL: "https://www.example.org/path", Host: "www.example.org"}}},
+		{name: "sentence end", in: "it's at example.com.", want: []chat.MessageURL{{Text: "example.com", URL: "https://example.com", Host: "example.com"}}},
+		{name: "wrapping parentheses", in: "(http://example.com/x)", want: []chat.MessageURL{{Text: "http://example.com/x", URL: "http://example.com/x", Host: "example.com"}}},
+		{name: "balanced parentheses", in: "read https://en.wikipedia.org/wiki/Go_(language), it's good", want: []chat.MessageURL{{Text: "https://en.wikipedia.org/wiki/Go_(language)", URL: "https://en.wikipedia.org/wiki/Go_(language)", Host: "en.wikipedia.org"}}},
+		{name: "quotes", in: `"example.net"`, want: []chat.MessageURL{{Text: "example.net", URL: "https://example.net", Host: "example.net"}}},
+		{name: "port", in: "example.com:8080/live", want: []chat.MessageURL{{Text: "example.com:8080/live", URL: "https://example.com:8080/live", Host: "example.com"}}},
+		{name: "IDN", in: "bücher.de", want: []chat.MessageURL{{Text: "bücher.de", URL: "https://xn--bcher-kva.de", Host: "xn--bcher-kva.de"}}},
+		{name: "IDN top-level domain", in: "http://例え.テスト/", want: []chat.MessageURL{{Text: "http://例え.テスト/", URL: "http://xn--r8jz45g.xn--zckzah/", Host: "xn--r8jz45g.xn--zckzah"}}},
+		{name: "ideographic full stop", in: "例え。テスト", want: []chat.MessageURL{{Text: "例え。テスト", URL: "https://xn--r8jz45g.xn--zckzah", Host: "xn--r8jz45g.xn--zckzah"}}},
+		{name: "punycode", in: "XN--BCHER-KVA.de", want: []chat.MessageURL{{Text: "XN--BCHER-KVA.de", URL: "https://xn--bcher-kva.de", Host: "xn--bcher-kva.de"}}},
+		{name: "look-alike host", in: "раураl.com", want: []chat.MessageURL{{Text: "раураl.com", URL: "https://xn--l-7sba6dbr.com", Host: "xn--l-7sba6dbr.com"}}},
+		{name: "userinfo", in: "https://paypal.com@evil.example/login", want: []chat.MessageURL{{Text: "https://paypal.com@evil.example/login", URL: "https://evil.example/login", Host: "evil.example"}}},
+		{name: "IPv4 with scheme", in: "http://192.0.2.1/", want: []chat.MessageURL{{Text: "http://192.0.2.1/", URL: "http://192.0.2.1/", Host: "192.0.2.1"}}},
+		{name: "several", in: "a.com and b.org", want: []chat.MessageURL{{Text: "a.com", URL: "https://a.com", Host: "a.com"}, {Text: "b.org", URL: "https://b.org", Host: "b.org"}}},
+		{name: "zero-width split", in: "exa\u200bmple.com", want: []chat.MessageURL{{Text: "example.com", URL: "https://example.com", Host: "example.com"}}},
+		{name: "abbreviation", in: "e.g. this"},
+		{name: "version number", in: "v1.2.3 and 3.14"},
+		{name: "e-mail address", in: "mail someone@example.com"},
+		{name: "other scheme", in: "ftp://example.com javascript:alert(1)"},
+		{name: "bare IPv4", in: "192.0.2.1"},
+		{name: "ellipsis", in: "well... ok"},
+		{name: "empty label", in: "a..com"},
+		{name: "hyphen edges", in: "-example.com example-.com"},
+		{name: "no domain", in: "https://localhost/"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			processed := chat.ProcessContent(tc.in, 0)
+			if !reflect.DeepEqual(processed.URLs, tc.want) {
+				t.Fatalf("ProcessContent(%q).URLs = %+v, want %+v", tc.in, processed.URLs, tc.want)
+			}
+			if extracted := chat.ExtractURLs(processed.Content); !reflect.DeepEqual(extracted, tc.want) {
+				t.Fatalf("ExtractURLs(%q) = %+v, want %+v", processed.Content, extracted, tc.want)
+			}
+		})
+	}
+}
+
+func TestGatewayRestrictsLinksFromNewUsers(t *testing.T) {
+	store := newTestStorage(t)
+	owner := mustCreateUser(t, store, storage.CreateUserParams{DisplayName: "owner", Email: "owner@example.com"})
+	follower := mustCreateUser(t, store, storage.CreateUserParams{DisplayName: "follower", Email: "follower@example.com"})
+	subscriber := mustCreateUser(t, store, storage.CreateUserParams{DisplayName: "subscriber", Email: "subscriber@example.com"})
+	newcomer := mustCreateUser(t, store, storage.CreateUserParams{DisplayName: "newcomer", Email: "newcomer@example.com"})
+	channel := mustCreateChannel(t, store, owner.ID, "Main")
+	percent, restrict := 50, true
+	if _, err := store.UpdateChannel(channel.ID, storage.ChannelUpdate{ChatMaxCapsPercent: &percent, ChatRestrictLinks: &restrict}); err != nil {
+		t.Fatalf("UpdateChannel: %v", err)
+	}
+	if err := store.FollowChannel(follower.ID, channel.ID); err != nil {
+		t.Fatalf("FollowChannel: %v", err)
+	}
+	if _, err := store.CreateSubscription(storage.CreateSubscriptionParams{ChannelID: channel.ID, UserID: subscriber.ID, Tier: "tier1", Provider: "stripe", Reference: "links-1", Amount: models.MustParseMoney("4.99"), Currency: "usd", Duration: time.Hour}); err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+
+	queue := &recordingQueue{}
+	gateway := chat.NewGateway(chat.GatewayConfig{Queue: queue, Store: store})
+	ctx := context.Background()
+
+	if _, err := gateway.CreateMessage(ctx, newcomer, channel.ID, "cheap followers at bücher.de", ""); !errors.Is(err, chat.ErrLinksRestricted) {
+		t.Fatalf("expected a newcomer's link to be refused, got %v", err)
+	}
+	shouted, err := gateway.CreateMessage(ctx, newcomer, channel.ID, "HELLO EVERYONE\u200b IN CHAT", "")
+	if err != nil || shouted.Content != "hello everyone in chat" || shouted.URLs != nil {
+		t.Fatalf("expected a processed message without links, got %+v (err %v)", shouted, err)
+	}
+	for _, author := range []models.User{follower, subscriber, owner} {
+		message, err := gateway.CreateMessage(ctx, author, channel.ID, "docs: bücher.de/Guide.", "")
+		if err != nil {
+			t.Fatalf("expected %s to be allowed to post links: %v", author.DisplayName, err)
+		}
+		want := []chat.MessageURL{{Text: "bücher.de/Guide", URL: "https://xn--bcher-kva.de/Guide", Host: "xn--bcher-kva.de"}}
+		if !reflect.DeepEqual(message.URLs, want) {
+			t.Fatalf("expected the extracted link on the message, got %+v", message.URLs)
+		}
+	}
+
+	published := queue.published()
+	if len(published) != 4 {
+		t.Fatalf("expected the refused message not to be queued, got %d events", len(published))
+	}
+	if queued := published[1].Message; len(queued.URLs) != 1 || queued.Content != "docs: bücher.de/Guide." {
+		t.Fatalf("expected the queued event to carry the processed content and links, got %+v", queued)
+	}
+}
+
+func TestGatewayRestrictsChatToSubscribers(t *testing.T) {
+	store := newTestStorage(t)
+	owner := mustCreateUser(t, store, storage.CreateUserParams{DisplayName: "owner", Email: "owner@example.com"})
+	moderator := mustCreateUser(t, store, storage.CreateUserParams{DisplayName: "moderator", Email: "moderator@example.com", Roles: []string{"moderator"}})
+	subscriber := mustCreateUser(t, store, storage.CreateUserParams{DisplayName: "subscriber", Email: "subscriber@example.com"})
+	newcomer := mustCreateUser(t, store, storage.CreateUserParams{DisplayName: "newcomer", Email: "newcomer@example.com"})
+	channel := mustCreateChannel(t, store, owner.ID, "Members")
+	subsOnly := true
+	if _, err := store.UpdateChannel(channel.ID, storage.ChannelUpdate{ChatSubscribersOnly: &subsOnly}); err != nil {
+		t.Fatalf("UpdateChannel: %v", err)
+	}
+	if _, err := store.CreateSubscription(storage.CreateSubscriptionParams{ChannelID: channel.ID, UserID: subscriber.ID, Tier: "tier1", Provider: "stripe", Reference: "subs-only-1", Amount: models.MustParseMoney("4.99"), Currency: "usd", Duration: time.Hour}); err != nil {
+		t.Fatalf("CreateSubscription: %v", err)
+	}
+
+	queue := &recordingQueue{}
+	gateway := chat.NewGateway(chat.GatewayConfig{Queue: queue, Store: store})
+	ctx := context.Background()
+
+	_, err := gateway.CreateMessage(ctx, newcomer, channel.ID, "let me in", "")
+	if !errors.Is(err, chat.ErrSubscribersOnly) {
+		t.Fatalf("expected a newcomer to be refused, got %v", err)
+	}
+	if code := chat.ErrorCode(err); code != chat.ErrorCodeSubscribersOnly {
+		t.Fatalf("expected the subscribers_only code, got %q", code)
+	}
+	for _, author := range []models.User{subscriber, moderator, owner} {
+		message, err := gateway.CreateMessage(ctx, author, channel.ID, "hello members", "")
+		if err != nil {
+			t.Fatalf("expected %s to be allowed to chat: %v", author.DisplayName, err)
+		}
+		if author.ID == subscriber.ID && (message.Author == nil || message.Author.SubscriberTier != 1) {
+			t.Fatalf("expected the subscriber's first-month tier, got %+v", message.Author)
+		}
+	}
+	if published := queue.published(); len(published) != 3 {
+		t.Fatalf("expected the refused message not to be queued, got %d events", len(published))
+	}
+}
@@ -0,0 +1,100 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+const (
+	// DefaultMaxDeliveries is how many times a reliable queue hands an event to
+	// consumers before moving it to the dead-letter queue.
+	DefaultMaxDeliveries = 5
+	// DefaultRetryDelay is how long a failed event waits before it is
+	// delivered again. The Redis queue uses it as the idle threshold for
+	// reclaiming pending entries.
+	DefaultRetryDelay = 30 * time.Second
+)
+
+// ErrDeadLetterNotFound is returned when a dead-lettered event does not exist.
+var ErrDeadLetterNotFound = errors.New("dead letter not found")
+
+// Delivery is an event handed to a consumer of a ReliableQueue. The consumer
+// settles it with Ack once the event has been handled or Fail when handling
+// it failed; failed events are delivered again until they exhaust the
+// queue's delivery limit and are dead-lettered.
+type Delivery struct {
+	ID      string
+	Event   Event
+	Attempt int
+
+	ack  func() error
+	fail func(error) error
+}
+
+// Ack marks the delivery as handled.
+func (d Delivery) Ack() error {
+	if d.ack == nil {
+		return nil
+	}
+	return d.ack()
+}
+
+// Fail reports that handling the delivery failed with cause.
+func (d Delivery) Fail(cause error) error {
+	if d.fail == nil {
+		return nil
+	}
+	if cause == nil {
+		cause = errors.New("delivery failed")
+	}
+	return d.fail(cause)
+}
+
+// DeliverySubscription streams deliveries to a single consumer.
+type DeliverySubscription interface {
+	Deliveries() <-chan Delivery
+	Close()
+}
+
+// DeadLetter is an event that failed on every delivery attempt. Payload holds
+// the encoded event as it was queued so undecodable events can still be
+// inspected.
+type DeadLetter struct {
+	ID       string
+	SourceID string
+	Event    Event
+	Payload  []byte
+	Error    string
+	Attempts int
+	FailedAt time.Time
+}
+
+// QueueStats summarises a reliable queue's backlog.
+type QueueStats struct {
+	// Depth counts events that have not been delivered to a consumer yet.
+	Depth int64
+	// Pending counts events delivered to a consumer but not yet settled,
+	// including failed events waiting to be retried.
+	Pending int64
+	// DeadLetters counts events in the dead-letter queue.
+	DeadLetters int64
+}
+
+// DeadLetterStore lets operators inspect and resolve dead-lettered events.
+type DeadLetterStore interface {
+	ListDeadLetters(ctx context.Context, limit int) ([]DeadLetter, error)
+	GetDeadLetter(ctx context.Context, id string) (DeadLetter, error)
+	RequeueDeadLetter(ctx context.Context, id string) error
+	DiscardDeadLetter(ctx context.Context, id string) error
+}
+
+// ReliableQueue is a Queue whose consumers acknowledge each event. Events that
+// keep failing are moved to a dead-letter queue instead of being retried
+// forever or dropped.
+type ReliableQueue interface {
+	Queue
+	DeadLetterStore
+	Consume() DeliverySubscription
+	Stats(ctx context.Context) (QueueStats, error)
+}
@@ -15,6 +15,17 @@ const (
 	EventTypeModeration EventType = "moderation"
 	// EventTypeReport represents a viewer-submitted moderation report.
 	EventTypeReport EventType = "report"
+	// EventTypeGift announces subscriptions gifted by a viewer to other
+	// members of the channel.
+	EventTypeGift EventType = "gift"
+	// EventTypeMarker announces a moment marked in the channel's live stream.
+	EventTypeMarker EventType = "marker"
+	// EventTypePin announces that a message was pinned above the channel's
+	// chat, or that the pin was removed.
+	EventTypePin EventType = "pin"
+	// EventTypeModerator announces that a user gained or lost a moderator
+	// grant on the channel.
+	EventTypeModerator EventType = "moderator"
 )
 
 // ModerationAction captures the different moderation operations available to
@@ -31,33 +42,57 @@ const (
 	ModerationActionBan ModerationAction = "ban"
 	// ModerationActionUnban removes a previously issued ban.
 	ModerationActionUnban ModerationAction = "unban"
+	// ModerationActionDeleteMessage removes a single message from the
+	// channel's chat.
+	ModerationActionDeleteMessage ModerationAction = "delete_message"
 )
 
-// Event is the wire representation forwarded to the persistence queue.
+// Event is the wire representation forwarded to the persistence queue. Seq
+// is the channel's chat sequence number; the gateway assigns one to message
+// and moderation events so reconnecting clients can resume where they left
+// off. Other events, and events sequenced while the store was unavailable,
+// carry zero.
 type Event struct {
 	Type       EventType        `json:"type"`
+	Seq        int64            `json:"seq,omitempty"`
 	Message    *MessageEvent    `json:"message,omitempty"`
 	Moderation *ModerationEvent `json:"moderation,omitempty"`
 	Report     *ReportEvent     `json:"report,omitempty"`
+	Gift       *GiftEvent       `json:"gift,omitempty"`
+	Marker     *MarkerEvent     `json:"marker,omitempty"`
+	Pin        *PinEvent        `json:"pin,omitempty"`
+	Moderator  *ModeratorEvent  `json:"moderator,omitempty"`
 	OccurredAt time.Time        `json:"occurredAt"`
 }
 
 // MessageEvent transports all information required to persist a chat message.
+// Author is only set on events delivered to clients; persistence relies on
+// UserID alone. IsFirstMessage marks the author's first message ever in the
+// channel. ClientMessageID echoes the ID the sender generated, so it can match
+// the event to its optimistic copy. URLs lists the links found in Content.
 type MessageEvent struct {
-	ID        string    `json:"id"`
-	ChannelID string    `json:"channelId"`
-	UserID    string    `json:"userId"`
-	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"createdAt"`
+	ID              string       `json:"id"`
+	ChannelID       string       `json:"channelId"`
+	UserID          string       `json:"userId"`
+	Content         string       `json:"content"`
+	URLs            []MessageURL `json:"urls,omitempty"`
+	ClientMessageID string       `json:"clientMessageId,omitempty"`
+	CreatedAt       time.Time    `json:"createdAt"`
+	IsFirstMessage  bool         `json:"isFirstMessage,omitempty"`
+	Author          *Author      `json:"author,omitempty"`
 }
 
 // ModerationEvent describes a moderation action taken by a moderator or
-// channel owner.
+// channel owner. ID identifies the action so a redelivered event is logged
+// once; the gateway assigns it. MessageID names the message a delete_message
+// action removes; TargetID, its author, is optional for that action.
 type ModerationEvent struct {
+	ID        string           `json:"id,omitempty"`
 	Action    ModerationAction `json:"action"`
 	ChannelID string           `json:"channelId"`
 	ActorID   string           `json:"actorId"`
 	TargetID  string           `json:"targetId"`
+	MessageID string           `json:"messageId,omitempty"`
 	ExpiresAt *time.Time       `json:"expiresAt,omitempty"`
 	Reason    string           `json:"reason,omitempty"`
 }
@@ -75,6 +110,80 @@ type ReportEvent struct {
 	CreatedAt   time.Time `json:"createdAt"`
 }
 
+// GiftEvent credits a viewer for gifting subscriptions. The subscriptions are
+// persisted before the event is emitted, so consumers treat it as an
+// announcement only.
+type GiftEvent struct {
+	ChannelID    string    `json:"channelId"`
+	GifterID     string    `json:"gifterId"`
+	RecipientIDs []string  `json:"recipientIds"`
+	Tier         string    `json:"tier"`
+	Count        int       `json:"count"`
+	CreatedAt    time.Time `json:"createdAt"`
+}
+
+// MarkerEvent lets overlay tools react to a stream marker. The marker is
+// persisted before the event is emitted, so consumers treat it as an
+// announcement only.
+type MarkerEvent struct {
+	ID            string    `json:"id"`
+	ChannelID     string    `json:"channelId"`
+	SessionID     string    `json:"sessionId"`
+	Label         string    `json:"label"`
+	OffsetSeconds int       `json:"offsetSeconds"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// PinAction distinguishes pinning a chat message from removing the pin.
+type PinAction string
+
+const (
+	// PinActionPin replaces the channel's pinned message.
+	PinActionPin PinAction = "pin"
+	// PinActionUnpin removes the channel's pinned message.
+	PinActionUnpin PinAction = "unpin"
+)
+
+// PinEvent tells clients to show or clear the message pinned above the
+// channel's chat. The pin is persisted before the event is emitted, so
+// consumers treat it as an announcement only. Unpin events carry the
+// removed pin.
+type PinEvent struct {
+	Action    PinAction `json:"action"`
+	ChannelID string    `json:"channelId"`
+	MessageID string    `json:"messageId,omitempty"`
+	AuthorID  string    `json:"authorId,omitempty"`
+	Content   string    `json:"content"`
+	PinnedBy  string    `json:"pinnedBy,omitempty"`
+	PinnedAt  time.Time `json:"pinnedAt"`
+}
+
+// ModeratorAction distinguishes granting a channel moderator from removing
+// the grant.
+type ModeratorAction string
+
+const (
+	// ModeratorActionAdded announces a new or extended moderator grant.
+	ModeratorActionAdded ModeratorAction = "added"
+	// ModeratorActionRemoved announces a revoked or expired moderator grant.
+	ModeratorActionRemoved ModeratorAction = "removed"
+)
+
+// ModeratorReasonExpired marks removals made because a time-limited grant
+// lapsed rather than because it was revoked.
+const ModeratorReasonExpired = "expired"
+
+// ModeratorEvent tells clients that a user gained or lost a moderator grant
+// on the channel. The grant is persisted before the event is emitted, so
+// consumers treat it as an announcement only.
+type ModeratorEvent struct {
+	Action    ModeratorAction `json:"action"`
+	ChannelID string          `json:"channelId"`
+	UserID    string          `json:"userId"`
+	ExpiresAt *time.Time      `json:"expiresAt,omitempty"`
+	Reason    string          `json:"reason,omitempty"`
+}
+
 // RestrictionsSnapshot represents the currently active moderation state for
 // each channel. It is primarily used to bootstrap the in-memory gateway view at
 // startup.
@@ -12,6 +12,7 @@ import (
 	"sync"
 	"time"
 
+	"bitriver-live/internal/authz"
 	"bitriver-live/internal/models"
 	"bitriver-live/internal/observability/metrics"
 )
@@ -24,6 +25,11 @@ type Store interface {
 	ChatRestrictions() RestrictionsSnapshot
 	IsChatBanned(channelID, userID string) bool
 	ChatTimeout(channelID, userID string) (time.Time, bool)
+	IsFollowingChannel(userID, channelID string) bool
+	IsChannelModerator(channelID, userID string) bool
+	AuthorStore
+	ChatterStore
+	SequenceStore
 }
 
 // GatewayConfig configures a chat Gateway.
@@ -44,11 +50,17 @@ type Gateway struct {
 	logger *slog.Logger
 
 	heartbeatInterval time.Duration
+	writeGuard        func(context.Context) error
 
 	mu       sync.RWMutex
 	rooms    map[string]map[*client]struct{}
 	bans     map[string]map[string]struct{}
 	timeouts map[string]map[string]time.Time
+
+	authors        authorCache
+	chatters       chatterCache
+	clientMessages clientMessageCache
+	sequencers     sequencers
 }
 
 // NewGateway initialises a gateway using the provided configuration.
@@ -93,6 +105,7 @@ func (g *Gateway) HandleConnection(w http.ResponseWriter, r *http.Request, user
 		user:    user,
 		send:    make(chan outboundMessage, 16),
 		rooms:   make(map[string]struct{}),
+		pending: make(map[string][]queuedEvent),
 		cancel:  cancel,
 	}
 
@@ -103,36 +116,168 @@ func (g *Gateway) HandleConnection(w http.ResponseWriter, r *http.Request, user
 	go c.readLoop(ctx)
 }
 
-// CreateMessage generates a new chat message authored by the given user.
-func (g *Gateway) CreateMessage(ctx context.Context, author models.User, channelID, content string) (MessageEvent, error) {
+// SetWriteGuard installs a check consulted before new messages are accepted.
+// Returning an error refuses the message, for example while the platform is in
+// read-only maintenance mode.
+func (g *Gateway) SetWriteGuard(guard func(context.Context) error) {
+	g.mu.Lock()
+	g.writeGuard = guard
+	g.mu.Unlock()
+}
+
+// CreateMessage generates a new chat message authored by the given user from
+// content as ProcessContent leaves it under the channel's settings. The
+// returned message and the broadcast event carry the resolved author; the
+// event forwarded to the queue only carries the author's ID. A resubmission
+// with the same optional clientMessageID inside ClientMessageIDWindow returns
+// the original message without broadcasting or queueing it again.
+func (g *Gateway) CreateMessage(ctx context.Context, author models.User, channelID, content, clientMessageID string) (MessageEvent, error) {
+	g.mu.RLock()
+	guard := g.writeGuard
+	g.mu.RUnlock()
+	if guard != nil {
+		if err := guard(ctx); err != nil {
+			return MessageEvent{}, err
+		}
+	}
 	if err := g.ensureChannelAccessible(channelID, author.ID); err != nil {
 		return MessageEvent{}, err
 	}
-	trimmed := strings.TrimSpace(content)
-	if trimmed == "" {
+	var channel models.Channel
+	if g.store != nil {
+		channel, _ = g.store.GetChannel(channelID)
+	}
+	processed := ProcessContent(content, channel.ChatMaxCapsPercent)
+	if processed.Content == "" {
 		return MessageEvent{}, fmt.Errorf("message cannot be empty")
 	}
-	if len([]rune(trimmed)) > 500 {
+	if len([]rune(processed.Content)) > 500 {
 		return MessageEvent{}, fmt.Errorf("message exceeds 500 characters")
 	}
+	clientMessageID, err := NormalizeClientMessageID(clientMessageID)
+	if err != nil {
+		return MessageEvent{}, err
+	}
+	resolved := g.resolveAuthor(channelID, author)
+	if channel.ChatSubscribersOnly && !ChatAllowed(channel, author, resolved.subscribed(), g.channelModerator(channel, author)) {
+		return MessageEvent{}, ErrSubscribersOnly
+	}
+	if len(processed.URLs) > 0 && !g.linksAllowed(channel, author, resolved) {
+		return MessageEvent{}, ErrLinksRestricted
+	}
 	id, err := generateID()
 	if err != nil {
 		return MessageEvent{}, err
 	}
 	message := MessageEvent{
-		ID:        id,
-		ChannelID: channelID,
-		UserID:    author.ID,
-		Content:   trimmed,
-		CreatedAt: time.Now().UTC(),
-	}
-	event := Event{Type: EventTypeMessage, Message: &message, OccurredAt: time.Now().UTC()}
-	g.broadcast(event)
-	g.publish(ctx, event)
+		ID:              id,
+		ChannelID:       channelID,
+		UserID:          author.ID,
+		Content:         processed.Content,
+		URLs:            processed.URLs,
+		ClientMessageID: clientMessageID,
+		CreatedAt:       time.Now().UTC(),
+	}
+	if clientMessageID != "" {
+		original, claimed := g.clientMessages.claim(message, message.CreatedAt)
+		if !claimed {
+			original.Author = &resolved
+			return original, nil
+		}
+	}
+	message.IsFirstMessage = g.isFirstMessage(channelID, author.ID)
+	stored := message
+	message.Author = &resolved
+	now := time.Now().UTC()
+	g.emitSequenced(ctx, channelID,
+		Event{Type: EventTypeMessage, Message: &message, OccurredAt: now},
+		Event{Type: EventTypeMessage, Message: &stored, OccurredAt: now})
 	metrics.Default().ObserveChatEvent("message")
 	return message, nil
 }
 
+// linksAllowed applies the channel's link restriction to author, whose
+// subscription is read from the resolved author.
+func (g *Gateway) linksAllowed(channel models.Channel, author models.User, resolved Author) bool {
+	if !channel.ChatRestrictLinks || g.store == nil {
+		return true
+	}
+	return LinksAllowed(channel, author, g.store.IsFollowingChannel(author.ID, channel.ID), resolved.subscribed(), g.channelModerator(channel, author))
+}
+
+// channelModerator reports whether user holds an unexpired moderator grant
+// on the channel. Grants are read from the store on every check so an
+// expired one stops applying at once.
+func (g *Gateway) channelModerator(channel models.Channel, user models.User) bool {
+	return g.store != nil && user.ID != "" && g.store.IsChannelModerator(channel.ID, user.ID)
+}
+
+// canModerate reports whether actor may moderate the channel, through their
+// role or a channel moderator grant.
+func (g *Gateway) canModerate(actor models.User, channel models.Channel) bool {
+	return authz.CanModerateChannel(actor, channel) || g.channelModerator(channel, actor)
+}
+
+// isFirstMessage reports whether userID has never chatted in the channel
+// before. Known chatters are answered from memory; lookup failures are logged
+// and treated as returning chatters so nobody is greeted twice.
+func (g *Gateway) isFirstMessage(channelID, userID string) bool {
+	if g.chatters.known(channelID, userID) {
+		return false
+	}
+	if g.store == nil {
+		return false
+	}
+	chatted, err := g.store.HasChatted(channelID, userID)
+	if err != nil {
+		if g.logger != nil {
+			g.logger.Warn("failed to look up chat history", "channel_id", channelID, "user_id", userID, "error", err)
+		}
+		return false
+	}
+	return g.chatters.add(channelID, userID) && !chatted
+}
+
+// InvalidateAuthor drops the user from every channel's author cache so the
+// next message picks up a changed name, avatar, role, or subscription.
+func (g *Gateway) InvalidateAuthor(userID string) {
+	g.authors.invalidateUser(userID)
+}
+
+// InvalidateChannelAuthors drops every cached author for the channel, for
+// example after it changes owner.
+func (g *Gateway) InvalidateChannelAuthors(channelID string) {
+	g.authors.invalidateChannel(channelID)
+}
+
+// resolveAuthor returns the author view for user in the channel, consulting
+// the per-channel cache first. Lookup failures fall back to the user's name
+// without badges and are not cached.
+func (g *Gateway) resolveAuthor(channelID string, user models.User) Author {
+	now := time.Now()
+	if author, ok := g.authors.get(channelID, user.ID, now); ok {
+		return author
+	}
+	fallback := Author{ID: user.ID, DisplayName: user.DisplayName, Badges: []string{}}
+	if g.store == nil {
+		return fallback
+	}
+	channel, ok := g.store.GetChannel(channelID)
+	if !ok {
+		return fallback
+	}
+	authors, err := ResolveAuthors(g.store, channel, []string{user.ID})
+	if err != nil {
+		if g.logger != nil {
+			g.logger.Warn("failed to resolve chat author", "channel_id", channelID, "user_id", user.ID, "error", err)
+		}
+		return fallback
+	}
+	author := authors[user.ID]
+	g.authors.put(channelID, author, now)
+	return author
+}
+
 // ApplyModeration emits a moderation event into the chat stream.
 func (g *Gateway) ApplyModeration(ctx context.Context, actor models.User, event ModerationEvent) error {
 	if err := g.validateModeration(actor, event); err != nil {
@@ -145,10 +290,16 @@ func (g *Gateway) ApplyModeration(ctx context.Context, actor models.User, event
 	if event.Action == ModerationActionTimeout && event.ExpiresAt != nil && event.ExpiresAt.Before(now) {
 		return fmt.Errorf("timeout expiry must be in the future")
 	}
+	// The gateway always picks the ID so a client cannot reuse one and have
+	// its action dropped from the moderation log as a redelivery.
+	id, err := generateID()
+	if err != nil {
+		return err
+	}
+	event.ID = id
 	evt := Event{Type: EventTypeModeration, Moderation: &event, OccurredAt: now}
 	g.applyModeration(event)
-	g.broadcast(evt)
-	g.publish(ctx, evt)
+	g.emitSequenced(ctx, event.ChannelID, evt, evt)
 	metrics.Default().ObserveChatEvent("moderation:" + string(event.Action))
 	return nil
 }
@@ -196,6 +347,177 @@ func (g *Gateway) SubmitReport(ctx context.Context, reporter models.User, channe
 	return report, nil
 }
 
+// AnnounceGift broadcasts a subscription gift to the channel room and forwards
+// it to the event queue.
+func (g *Gateway) AnnounceGift(ctx context.Context, gift GiftEvent) error {
+	if gift.ChannelID == "" || gift.GifterID == "" {
+		return fmt.Errorf("channel and gifter are required")
+	}
+	if gift.Count <= 0 {
+		gift.Count = len(gift.RecipientIDs)
+	}
+	if gift.CreatedAt.IsZero() {
+		gift.CreatedAt = time.Now().UTC()
+	}
+	evt := Event{Type: EventTypeGift, Gift: &gift, OccurredAt: gift.CreatedAt}
+	g.broadcast(evt)
+	g.publish(ctx, evt)
+	metrics.Default().ObserveChatEvent("gift")
+	return nil
+}
+
+// AnnounceMarker broadcasts a stream marker to the channel room and forwards
+// it to the event queue.
+func (g *Gateway) AnnounceMarker(ctx context.Context, marker MarkerEvent) error {
+	if marker.ChannelID == "" || marker.ID == "" {
+		return fmt.Errorf("channel and marker are required")
+	}
+	if marker.CreatedAt.IsZero() {
+		marker.CreatedAt = time.Now().UTC()
+	}
+	evt := Event{Type: EventTypeMarker, Marker: &marker, OccurredAt: marker.CreatedAt}
+	g.broadcast(evt)
+	g.publish(ctx, evt)
+	metrics.Default().ObserveChatEvent("marker")
+	return nil
+}
+
+// AnnouncePin broadcasts a pin or unpin to the channel room and forwards it
+// to the event queue.
+func (g *Gateway) AnnouncePin(ctx context.Context, pin PinEvent) error {
+	if pin.ChannelID == "" {
+		return fmt.Errorf("channel is required")
+	}
+	if pin.Action != PinActionPin && pin.Action != PinActionUnpin {
+		return fmt.Errorf("unsupported pin action %q", pin.Action)
+	}
+	now := time.Now().UTC()
+	if pin.PinnedAt.IsZero() {
+		pin.PinnedAt = now
+	}
+	evt := Event{Type: EventTypePin, Pin: &pin, OccurredAt: now}
+	g.broadcast(evt)
+	g.publish(ctx, evt)
+	metrics.Default().ObserveChatEvent("pin")
+	return nil
+}
+
+// AnnounceModerator broadcasts a moderator grant change to the channel room
+// and forwards it to the event queue. The user's cached author views are
+// dropped so their next message carries the right badge.
+func (g *Gateway) AnnounceModerator(ctx context.Context, moderator ModeratorEvent) error {
+	if moderator.ChannelID == "" || moderator.UserID == "" {
+		return fmt.Errorf("channel and user are required")
+	}
+	if moderator.Action != ModeratorActionAdded && moderator.Action != ModeratorActionRemoved {
+		return fmt.Errorf("unsupported moderator action %q", moderator.Action)
+	}
+	g.authors.invalidateUser(moderator.UserID)
+	evt := Event{Type: EventTypeModerator, Moderator: &moderator, OccurredAt: time.Now().UTC()}
+	g.broadcast(evt)
+	g.publish(ctx, evt)
+	metrics.Default().ObserveChatEvent("moderator")
+	return nil
+}
+
+// emitSequenced stamps live and stored, the broadcast and queued copies of
+// one event, with the channel's next sequence number, then broadcasts and
+// publishes them. The channel's sequencer is held throughout so clients and
+// the queue see the channel's events in sequence order. When the store cannot
+// issue a number the event goes out unsequenced rather than being lost.
+func (g *Gateway) emitSequenced(ctx context.Context, channelID string, live, stored Event) {
+	seq := g.sequencers.get(channelID)
+	seq.mu.Lock()
+	defer seq.mu.Unlock()
+	if g.store != nil {
+		next, err := g.store.NextChatSequence(channelID)
+		if err != nil {
+			if g.logger != nil {
+				g.logger.Warn("failed to assign chat sequence", "channel_id", channelID, "error", err)
+			}
+		} else {
+			live.Seq, stored.Seq = next, next
+			seq.remember(live)
+		}
+	}
+	g.broadcast(live)
+	g.publish(ctx, stored)
+}
+
+// backfill builds the reply to a client resuming the channel after lastSeq.
+// Recent events come from memory and older ones from the store. A client
+// that missed more than MaxBackfillEvents events, or events older than
+// MaxBackfillAge, gets an overflow reply carrying only the latest sequence
+// number and must reload the chat history.
+func (g *Gateway) backfill(channelID string, lastSeq int64) backfillMessage {
+	reply := backfillMessage{Type: "backfill", ChannelID: channelID, Events: []Event{}, LastSeq: lastSeq}
+	events, head, complete := g.sequencers.get(channelID).since(lastSeq)
+	if !complete {
+		stored, err := g.store.ChatEventsSince(channelID, lastSeq, MaxBackfillEvents+1)
+		if err != nil {
+			if g.logger != nil {
+				g.logger.Warn("failed to load chat backfill", "channel_id", channelID, "error", err)
+			}
+			reply.Overflow = true
+			if head > reply.LastSeq {
+				reply.LastSeq = head
+			}
+			return reply
+		}
+		events = mergeSequenced(g.withAuthors(channelID, stored), events)
+	}
+	if len(events) == 0 {
+		return reply
+	}
+	last := events[len(events)-1].Seq
+	if head < last {
+		head = last
+	}
+	if len(events) > MaxBackfillEvents || time.Since(events[0].OccurredAt) > MaxBackfillAge {
+		reply.Overflow = true
+		reply.LastSeq = head
+		return reply
+	}
+	reply.Events = events
+	reply.LastSeq = last
+	return reply
+}
+
+// withAuthors attaches the resolved author to stored message events, which
+// only carry the author's ID.
+func (g *Gateway) withAuthors(channelID string, events []Event) []Event {
+	ids := make([]string, 0, len(events))
+	for _, evt := range events {
+		if evt.Message != nil {
+			ids = append(ids, evt.Message.UserID)
+		}
+	}
+	if len(ids) == 0 {
+		return events
+	}
+	channel, ok := g.store.GetChannel(channelID)
+	if !ok {
+		return events
+	}
+	authors, err := ResolveAuthors(g.store, channel, ids)
+	if err != nil {
+		if g.logger != nil {
+			g.logger.Warn("failed to resolve chat backfill authors", "channel_id", channelID, "error", err)
+		}
+		return events
+	}
+	for i, evt := range events {
+		if evt.Message == nil {
+			continue
+		}
+		message := *evt.Message
+		author := authors[message.UserID]
+		message.Author = &author
+		events[i].Message = &message
+	}
+	return events
+}
+
 func (g *Gateway) publish(ctx context.Context, event Event) {
 	if g.queue == nil {
 		return
@@ -227,7 +549,11 @@ func (g *Gateway) ensureChannelAccessible(channelID, userID string) error {
 }
 
 func (g *Gateway) validateModeration(actor models.User, evt ModerationEvent) error {
-	if evt.ChannelID == "" || evt.TargetID == "" {
+	if evt.Action == ModerationActionDeleteMessage {
+		if evt.ChannelID == "" || evt.MessageID == "" {
+			return fmt.Errorf("channel and message are required")
+		}
+	} else if evt.ChannelID == "" || evt.TargetID == "" {
 		return fmt.Errorf("channel and target are required")
 	}
 	if g.store == nil {
@@ -237,7 +563,7 @@ func (g *Gateway) validateModeration(actor models.User, evt ModerationEvent) err
 	if !exists {
 		return fmt.Errorf("channel %s not found", evt.ChannelID)
 	}
-	if actor.ID != channel.OwnerID && !actor.HasRole("admin") {
+	if !g.canModerate(actor, channel) {
 		return fmt.Errorf("forbidden")
 	}
 	if evt.Action == ModerationActionTimeout && evt.ExpiresAt == nil {
@@ -264,6 +590,12 @@ func (g *Gateway) broadcast(event Event) {
 		channelID = event.Moderation.ChannelID
 	} else if event.Report != nil {
 		channelID = event.Report.ChannelID
+	} else if event.Gift != nil {
+		channelID = event.Gift.ChannelID
+	} else if event.Marker != nil {
+		channelID = event.Marker.ChannelID
+	} else if event.Pin != nil {
+		channelID = event.Pin.ChannelID
 	}
 	if channelID == "" {
 		return
@@ -280,10 +612,7 @@ func (g *Gateway) broadcast(event Event) {
 		return
 	}
 	for client := range recipients {
-		select {
-		case client.send <- outboundMessage{Raw: payload}:
-		default:
-		}
+		client.deliver(channelID, event.Seq, payload)
 	}
 }
 
@@ -373,26 +702,53 @@ type client struct {
 	rooms   map[string]struct{}
 	closed  sync.Once
 	cancel  context.CancelFunc
+
+	// pending holds the live events of channels whose backfill is still
+	// being sent, keyed by channel ID.
+	mu      sync.Mutex
+	pending map[string][]queuedEvent
+}
+
+// queuedEvent is a live event held back until a resuming client's backfill
+// has been sent.
+type queuedEvent struct {
+	seq     int64
+	payload []byte
 }
 
 type inboundMessage struct {
-	Type       string `json:"type"`
-	ChannelID  string `json:"channelId"`
-	Content    string `json:"content"`
-	TargetID   string `json:"targetId"`
-	DurationMs int    `json:"durationMs"`
-	Reason     string `json:"reason"`
-	MessageID  string `json:"messageId"`
-	Evidence   string `json:"evidenceUrl"`
+	Type            string `json:"type"`
+	ChannelID       string `json:"channelId"`
+	Content         string `json:"content"`
+	ClientMessageID string `json:"clientMessageId"`
+	TargetID        string `json:"targetId"`
+	DurationMs      int    `json:"durationMs"`
+	Reason          string `json:"reason"`
+	MessageID       string `json:"messageId"`
+	Evidence        string `json:"evidenceUrl"`
+	LastSeq         *int64 `json:"lastSeq"`
 }
 
 type outboundMessage struct {
 	Type  string `json:"type,omitempty"`
 	Error string `json:"error,omitempty"`
+	Code  string `json:"code,omitempty"`
 	Event *Event `json:"event,omitempty"`
 	Raw   []byte `json:"-"`
 }
 
+// backfillMessage answers a join that resumes from lastSeq. Events lists the
+// missed events in sequence order and LastSeq is the number to resume from
+// next time. Overflow means too much was missed: Events is empty and the
+// client must reload the chat history.
+type backfillMessage struct {
+	Type      string  `json:"type"`
+	ChannelID string  `json:"channelId"`
+	Events    []Event `json:"events"`
+	LastSeq   int64   `json:"lastSeq"`
+	Overflow  bool    `json:"overflow,omitempty"`
+}
+
 func (c *client) writeLoop() {
 	defer c.close()
 	for msg := range c.send {
@@ -440,7 +796,7 @@ func (c *client) readLoop(ctx context.Context) {
 		}
 		switch msg.Type {
 		case "join":
-			c.handleJoin(msg.ChannelID)
+			c.handleJoin(msg.ChannelID, msg.LastSeq)
 		case "leave":
 			c.handleLeave(msg.ChannelID)
 		case "message":
@@ -461,7 +817,12 @@ func (c *client) readLoop(ctx context.Context) {
 	}
 }
 
-func (c *client) handleJoin(channelID string) {
+// handleJoin adds the client to the channel's room. A join carrying lastSeq
+// resumes an earlier subscription: live events are held back while the
+// events numbered above lastSeq are sent as one backfill message, then
+// released without the ones the backfill already covered, so the client sees
+// no gap and no duplicate.
+func (c *client) handleJoin(channelID string, lastSeq *int64) {
 	if channelID == "" {
 		c.sendError("channel required")
 		return
@@ -470,6 +831,12 @@ func (c *client) handleJoin(channelID string) {
 		c.sendError(err.Error())
 		return
 	}
+	resuming := lastSeq != nil && c.gateway.store != nil
+	if resuming {
+		c.mu.Lock()
+		c.pending[channelID] = []queuedEvent{}
+		c.mu.Unlock()
+	}
 	c.gateway.mu.Lock()
 	if c.gateway.rooms[channelID] == nil {
 		c.gateway.rooms[channelID] = make(map[*client]struct{})
@@ -480,6 +847,58 @@ func (c *client) handleJoin(channelID string) {
 
 	payload, _ := json.Marshal(outboundMessage{Type: "ack"})
 	c.send <- outboundMessage{Raw: payload}
+	if resuming {
+		c.resume(channelID, *lastSeq)
+	}
+}
+
+// resume sends the backfill after lastSeq and then releases the live events
+// held back meanwhile, skipping those the backfill already included. Events
+// broadcast while earlier ones are released are held back in turn, so they
+// keep their order.
+func (c *client) resume(channelID string, lastSeq int64) {
+	reply := c.gateway.backfill(channelID, lastSeq)
+	payload, _ := json.Marshal(reply)
+	c.send <- outboundMessage{Raw: payload}
+
+	for {
+		c.mu.Lock()
+		queued, ok := c.pending[channelID]
+		if !ok || len(queued) == 0 {
+			delete(c.pending, channelID)
+			c.mu.Unlock()
+			return
+		}
+		c.pending[channelID] = []queuedEvent{}
+		c.mu.Unlock()
+		for _, evt := range queued {
+			if evt.seq > 0 && evt.seq <= reply.LastSeq {
+				continue
+			}
+			c.send <- outboundMessage{Raw: evt.payload}
+		}
+	}
+}
+
+// deliver sends a broadcast payload to the client, or holds it back while
+// the channel's backfill is being sent.
+func (c *client) deliver(channelID string, seq int64, payload []byte) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if queued, ok := c.pending[channelID]; ok {
+		c.pending[channelID] = append(queued, queuedEvent{seq: seq, payload: payload})
+		return
+	}
+	c.trySend(payload)
+}
+
+// trySend queues payload for writing, dropping it when the client is too
+// far behind.
+func (c *client) trySend(payload []byte) {
+	select {
+	case c.send <- outboundMessage{Raw: payload}:
+	default:
+	}
 }
 
 func (c *client) handleLeave(channelID string) {
@@ -495,6 +914,9 @@ func (c *client) handleLeave(channelID string) {
 	}
 	c.gateway.mu.Unlock()
 	delete(c.rooms, channelID)
+	c.mu.Lock()
+	delete(c.pending, channelID)
+	c.mu.Unlock()
 }
 
 func (c *client) handleMessage(msg inboundMessage) {
@@ -506,9 +928,9 @@ func (c *client) handleMessage(msg inboundMessage) {
 		c.sendError("join channel first")
 		return
 	}
-	event, err := c.gateway.CreateMessage(context.Background(), c.user, msg.ChannelID, msg.Content)
+	event, err := c.gateway.CreateMessage(context.Background(), c.user, msg.ChannelID, msg.Content, msg.ClientMessageID)
 	if err != nil {
-		c.sendError(err.Error())
+		c.sendFailure(err)
 		return
 	}
 	ack := Event{Type: EventTypeMessage, Message: &event, OccurredAt: time.Now().UTC()}
@@ -566,7 +988,16 @@ func (c *client) handleReport(msg inboundMessage) {
 }
 
 func (c *client) sendError(message string) {
-	payload, _ := json.Marshal(outboundMessage{Type: "error", Error: message})
+	c.sendErrorMessage(outboundMessage{Type: "error", Error: message})
+}
+
+// sendFailure reports err along with its ErrorCode, if it has one.
+func (c *client) sendFailure(err error) {
+	c.sendErrorMessage(outboundMessage{Type: "error", Error: err.Error(), Code: ErrorCode(err)})
+}
+
+func (c *client) sendErrorMessage(msg outboundMessage) {
+	payload, _ := json.Marshal(msg)
 	select {
 	case c.send <- outboundMessage{Raw: payload}:
 	default:
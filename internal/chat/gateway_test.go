@@ -25,7 +25,9 @@ func TestGatewayMessageFlow(t *testing.T) {
 	gateway := chat.NewGateway(chat.GatewayConfig{Queue: queue, Store: store})
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	go storage.NewChatWorker(store, queue, nil).Run(ctx)
+	started := make(chan struct{})
+	go storage.NewChatWorker(store, queue, nil).WithStartedChannel(started).Run(ctx)
+	<-started
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		userID := r.URL.Query().Get("user")
@@ -82,7 +84,9 @@ func TestGatewayModerationFlow(t *testing.T) {
 	gateway := chat.NewGateway(chat.GatewayConfig{Queue: queue, Store: store})
 	ctx, cancel := context.WithCancel(context.Background())
 	defer cancel()
-	go storage.NewChatWorker(store, queue, nil).Run(ctx)
+	started := make(chan struct{})
+	go storage.NewChatWorker(store, queue, nil).WithStartedChannel(started).Run(ctx)
+	<-started
 
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		userID := r.URL.Query().Get("user")
@@ -152,6 +156,58 @@ func TestGatewayApplyModerationWithoutStore(t *testing.T) {
 	}
 }
 
+func TestGatewayChannelModeratorGrantExpires(t *testing.T) {
+	now := time.Now().UTC()
+	store, err := storage.NewStorage(t.TempDir()+"/store.json", storage.WithGrantClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("NewStorage: %v", err)
+	}
+	owner := mustCreateUser(t, store, storage.CreateUserParams{DisplayName: "owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	guest := mustCreateUser(t, store, storage.CreateUserParams{DisplayName: "guest", Email: "guest@example.com"})
+	viewer := mustCreateUser(t, store, storage.CreateUserParams{DisplayName: "viewer", Email: "viewer@example.com"})
+	channel := mustCreateChannel(t, store, owner.ID, "Main")
+	gateway := chat.NewGateway(chat.GatewayConfig{Queue: chat.NewMemoryQueue(16), Store: store})
+	ctx := context.Background()
+	ban := chat.ModerationEvent{Action: chat.ModerationActionBan, ChannelID: channel.ID, TargetID: viewer.ID}
+
+	expiresAt := now.Add(time.Hour)
+	if _, err := store.GrantChannelModerator(channel.ID, guest.ID, owner.ID, &expiresAt); err != nil {
+		t.Fatalf("GrantChannelModerator: %v", err)
+	}
+	if err := gateway.AnnounceModerator(ctx, chat.ModeratorEvent{Action: chat.ModeratorActionAdded, ChannelID: channel.ID, UserID: guest.ID, ExpiresAt: &expiresAt}); err != nil {
+		t.Fatalf("AnnounceModerator: %v", err)
+	}
+	message, err := gateway.CreateMessage(ctx, guest, channel.ID, "hosting tonight", "")
+	if err != nil {
+		t.Fatalf("CreateMessage: %v", err)
+	}
+	if message.Author == nil || len(message.Author.Badges) == 0 || message.Author.Badges[0] != chat.BadgeModerator {
+		t.Fatalf("expected the guest to carry the moderator badge, got %+v", message.Author)
+	}
+	if err := gateway.ApplyModeration(ctx, guest, ban); err != nil {
+		t.Fatalf("expected a channel moderator to ban, got %v", err)
+	}
+
+	now = expiresAt
+	unban := chat.ModerationEvent{Action: chat.ModerationActionUnban, ChannelID: channel.ID, TargetID: viewer.ID}
+	if err := gateway.ApplyModeration(ctx, guest, unban); err == nil || err.Error() != "forbidden" {
+		t.Fatalf("expected moderation to be forbidden once the grant expires, got %v", err)
+	}
+	if err := gateway.AnnounceModerator(ctx, chat.ModeratorEvent{Action: chat.ModeratorActionRemoved, ChannelID: channel.ID, UserID: guest.ID, Reason: chat.ModeratorReasonExpired}); err != nil {
+		t.Fatalf("AnnounceModerator: %v", err)
+	}
+	message, err = gateway.CreateMessage(ctx, guest, channel.ID, "thanks all", "")
+	if err != nil {
+		t.Fatalf("CreateMessage: %v", err)
+	}
+	if message.Author == nil || len(message.Author.Badges) != 0 {
+		t.Fatalf("expected the badge to be dropped once the removal is announced, got %+v", message.Author)
+	}
+	if err := gateway.AnnounceModerator(ctx, chat.ModeratorEvent{Action: "promoted", ChannelID: channel.ID, UserID: guest.ID}); err == nil {
+		t.Fatal("expected an unknown moderator action to be rejected")
+	}
+}
+
 func newTestStorage(t *testing.T) *storage.Storage {
 	t.Helper()
 	tempDir := t.TempDir()
@@ -0,0 +1,107 @@
+package chat
+
+import (
+	"errors"
+	"strings"
+)
+
+// Punycode parameters from RFC 3492, section 5.
+const (
+	punycodeBase        = 36
+	punycodeTMin        = 1
+	punycodeTMax        = 26
+	punycodeSkew        = 38
+	punycodeDamp        = 700
+	punycodeInitialBias = 72
+	punycodeInitialN    = 128
+	punycodeACEPrefix   = "xn--"
+)
+
+var errPunycodeOverflow = errors.New("punycode: label too long")
+
+// punycodeLabel returns the ASCII form of a single domain label, encoding
+// labels with non-ASCII runes as "xn--" punycode. The label is expected to be
+// lower-cased and NFC-normalized already.
+func punycodeLabel(label string) (string, error) {
+	runes := []rune(label)
+	var out strings.Builder
+	basic := 0
+	for _, r := range runes {
+		if r < punycodeInitialN {
+			out.WriteRune(r)
+			basic++
+		}
+	}
+	if basic == len(runes) {
+		return label, nil
+	}
+	handled := basic
+	if basic > 0 {
+		out.WriteByte('-')
+	}
+	n, delta, bias := rune(punycodeInitialN), 0, punycodeInitialBias
+	for handled < len(runes) {
+		m := rune(0x10FFFF)
+		for _, r := range runes {
+			if r >= n && r < m {
+				m = r
+			}
+		}
+		if int(m-n) > (1<<30)/(handled+1) {
+			return "", errPunycodeOverflow
+		}
+		delta += int(m-n) * (handled + 1)
+		n = m
+		for _, r := range runes {
+			if r < n {
+				delta++
+			}
+			if r != n {
+				continue
+			}
+			q := delta
+			for k := punycodeBase; ; k += punycodeBase {
+				t := k - bias
+				if t < punycodeTMin {
+					t = punycodeTMin
+				} else if t > punycodeTMax {
+					t = punycodeTMax
+				}
+				if q < t {
+					break
+				}
+				out.WriteByte(punycodeDigit(t + (q-t)%(punycodeBase-t)))
+				q = (q - t) / (punycodeBase - t)
+			}
+			out.WriteByte(punycodeDigit(q))
+			bias = punycodeAdapt(delta, handled+1, handled == basic)
+			delta = 0
+			handled++
+		}
+		delta++
+		n++
+	}
+	return punycodeACEPrefix + out.String(), nil
+}
+
+func punycodeDigit(d int) byte {
+	if d < 26 {
+		return byte('a' + d)
+	}
+	return byte('0' + d - 26)
+}
+
+func punycodeAdapt(delta, points int, first bool) int {
+	if first {
+		delta /= punycodeDamp
+	} else {
+		delta /= 2
+	}
+	delta += delta / points
+	k := 0
+	for delta > ((punycodeBase-punycodeTMin)*punycodeTMax)/2 {
+		delta /= punycodeBase - punycodeTMin
+		k += punycodeBase
+	}
+	return k + (punycodeBase-punycodeTMin+1)*delta/(delta+punycodeSkew)
+}
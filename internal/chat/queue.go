@@ -2,8 +2,14 @@ package chat
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
+	"sort"
+	"strconv"
 	"sync"
+	"time"
+
+	"bitriver-live/internal/observability/metrics"
 )
 
 // Queue fan-outs chat events to interested subscribers. The implementation is
@@ -20,22 +26,63 @@ type Subscription interface {
 	Close()
 }
 
+// MemoryQueueConfig configures the in-memory queue. MaxDeliveries defaults to
+// DefaultMaxDeliveries, RetryDelay to DefaultRetryDelay, and Metrics to
+// metrics.Default.
+type MemoryQueueConfig struct {
+	Buffer        int
+	MaxDeliveries int
+	RetryDelay    time.Duration
+	Metrics       *metrics.Recorder
+}
+
 // NewMemoryQueue initialises an in-memory fan-out queue suitable for tests and
 // single-process deployments.
 func NewMemoryQueue(buffer int) Queue {
-	if buffer <= 0 {
-		buffer = 32
+	return NewMemoryQueueWithConfig(MemoryQueueConfig{Buffer: buffer})
+}
+
+// NewMemoryQueueWithConfig initialises an in-memory queue that also emulates
+// the Redis queue's retry and dead-letter behaviour for consumers, so the chat
+// worker handles failures the same way in both deployments.
+func NewMemoryQueueWithConfig(cfg MemoryQueueConfig) ReliableQueue {
+	if cfg.Buffer <= 0 {
+		cfg.Buffer = 32
+	}
+	if cfg.MaxDeliveries <= 0 {
+		cfg.MaxDeliveries = DefaultMaxDeliveries
+	}
+	if cfg.RetryDelay <= 0 {
+		cfg.RetryDelay = DefaultRetryDelay
+	}
+	if cfg.Metrics == nil {
+		cfg.Metrics = metrics.Default()
 	}
 	return &memoryQueue{
-		subs:   make(map[*memorySubscription]struct{}),
-		buffer: buffer,
+		subs:          make(map[*memorySubscription]struct{}),
+		consumers:     make(map[*memoryConsumer]struct{}),
+		buffer:        cfg.Buffer,
+		maxDeliveries: cfg.MaxDeliveries,
+		retryDelay:    cfg.RetryDelay,
+		metrics:       cfg.Metrics,
 	}
 }
 
 type memoryQueue struct {
-	mu     sync.RWMutex
-	subs   map[*memorySubscription]struct{}
-	buffer int
+	mu            sync.RWMutex
+	subs          map[*memorySubscription]struct{}
+	consumers     map[*memoryConsumer]struct{}
+	buffer        int
+	maxDeliveries int
+	retryDelay    time.Duration
+	metrics       *metrics.Recorder
+
+	// stateMu guards delivery bookkeeping separately from the subscriber
+	// maps so settling a delivery never waits on Publish.
+	stateMu     sync.Mutex
+	nextID      uint64
+	pending     int64
+	deadLetters []DeadLetter
 }
 
 func (q *memoryQueue) Publish(ctx context.Context, event Event) error {
@@ -43,17 +90,25 @@ func (q *memoryQueue) Publish(ctx context.Context, event Event) error {
 		return errors.New("event type is required")
 	}
 	q.mu.RLock()
-	defer q.mu.RUnlock()
 	for sub := range q.subs {
 		select {
 		case sub.ch <- event:
 		case <-ctx.Done():
+			q.mu.RUnlock()
 			return ctx.Err()
 		default:
 			// Drop instead of blocking to keep the live path
 			// responsive. Consumers are expected to drain promptly.
 		}
 	}
+	consumers := make([]*memoryConsumer, 0, len(q.consumers))
+	for consumer := range q.consumers {
+		consumers = append(consumers, consumer)
+	}
+	q.mu.RUnlock()
+	for _, consumer := range consumers {
+		q.offer(consumer, q.newDelivery(consumer, event, 1))
+	}
 	return nil
 }
 
@@ -68,6 +123,153 @@ func (q *memoryQueue) Subscribe() Subscription {
 	return sub
 }
 
+// Consume registers a consumer that receives every published event as a
+// Delivery. Like subscriptions, a consumer with a full buffer misses events
+// rather than blocking publishers.
+func (q *memoryQueue) Consume() DeliverySubscription {
+	consumer := &memoryConsumer{
+		queue: q,
+		ch:    make(chan Delivery, q.buffer),
+	}
+	q.mu.Lock()
+	q.consumers[consumer] = struct{}{}
+	q.mu.Unlock()
+	return consumer
+}
+
+func (q *memoryQueue) newDelivery(consumer *memoryConsumer, event Event, attempt int) Delivery {
+	q.stateMu.Lock()
+	q.nextID++
+	id := strconv.FormatUint(q.nextID, 10)
+	q.stateMu.Unlock()
+	return q.redelivery(consumer, id, event, attempt)
+}
+
+func (q *memoryQueue) redelivery(consumer *memoryConsumer, id string, event Event, attempt int) Delivery {
+	var once sync.Once
+	return Delivery{
+		ID:      id,
+		Event:   event,
+		Attempt: attempt,
+		ack: func() error {
+			once.Do(func() { q.settle(nil) })
+			return nil
+		},
+		fail: func(cause error) error {
+			once.Do(func() {
+				if attempt >= q.maxDeliveries {
+					q.settle(&DeadLetter{SourceID: id, Event: event, Error: cause.Error(), Attempts: attempt})
+					return
+				}
+				time.AfterFunc(q.retryDelay, func() {
+					q.offer(consumer, q.redelivery(consumer, id, event, attempt+1))
+					q.settle(nil)
+				})
+			})
+			return nil
+		},
+	}
+}
+
+// offer hands a delivery to consumer and counts it as pending until settled.
+func (q *memoryQueue) offer(consumer *memoryConsumer, delivery Delivery) {
+	q.stateMu.Lock()
+	q.pending++
+	q.stateMu.Unlock()
+	if !consumer.offer(delivery) {
+		q.settle(nil)
+		return
+	}
+	q.recordStats()
+}
+
+// settle retires a pending delivery, dead-lettering it when letter is set.
+func (q *memoryQueue) settle(letter *DeadLetter) {
+	q.stateMu.Lock()
+	q.pending--
+	if letter != nil {
+		q.nextID++
+		letter.ID = strconv.FormatUint(q.nextID, 10)
+		letter.FailedAt = time.Now().UTC()
+		if payload, err := json.Marshal(letter.Event); err == nil {
+			letter.Payload = payload
+		}
+		q.deadLetters = append(q.deadLetters, *letter)
+	}
+	q.stateMu.Unlock()
+	q.recordStats()
+}
+
+// Stats reports no depth because Publish hands events straight to consumer
+// buffers; buffered, in-flight, and retrying events all count as pending.
+func (q *memoryQueue) Stats(context.Context) (QueueStats, error) {
+	q.stateMu.Lock()
+	defer q.stateMu.Unlock()
+	return QueueStats{Pending: q.pending, DeadLetters: int64(len(q.deadLetters))}, nil
+}
+
+func (q *memoryQueue) recordStats() {
+	stats, _ := q.Stats(context.Background())
+	q.metrics.SetChatQueueStats(stats.Depth, stats.Pending, stats.DeadLetters)
+}
+
+func (q *memoryQueue) ListDeadLetters(_ context.Context, limit int) ([]DeadLetter, error) {
+	q.stateMu.Lock()
+	defer q.stateMu.Unlock()
+	letters := append([]DeadLetter(nil), q.deadLetters...)
+	sort.SliceStable(letters, func(i, j int) bool { return letters[i].FailedAt.Before(letters[j].FailedAt) })
+	if limit > 0 && len(letters) > limit {
+		letters = letters[:limit]
+	}
+	return letters, nil
+}
+
+func (q *memoryQueue) GetDeadLetter(_ context.Context, id string) (DeadLetter, error) {
+	q.stateMu.Lock()
+	defer q.stateMu.Unlock()
+	for _, letter := range q.deadLetters {
+		if letter.ID == id {
+			return letter, nil
+		}
+	}
+	return DeadLetter{}, ErrDeadLetterNotFound
+}
+
+func (q *memoryQueue) RequeueDeadLetter(ctx context.Context, id string) error {
+	letter, err := q.removeDeadLetter(id)
+	if err != nil {
+		return err
+	}
+	if err := q.Publish(ctx, letter.Event); err != nil {
+		q.stateMu.Lock()
+		q.deadLetters = append(q.deadLetters, letter)
+		q.stateMu.Unlock()
+		return err
+	}
+	q.recordStats()
+	return nil
+}
+
+func (q *memoryQueue) DiscardDeadLetter(_ context.Context, id string) error {
+	if _, err := q.removeDeadLetter(id); err != nil {
+		return err
+	}
+	q.recordStats()
+	return nil
+}
+
+func (q *memoryQueue) removeDeadLetter(id string) (DeadLetter, error) {
+	q.stateMu.Lock()
+	defer q.stateMu.Unlock()
+	for i, letter := range q.deadLetters {
+		if letter.ID == id {
+			q.deadLetters = append(q.deadLetters[:i], q.deadLetters[i+1:]...)
+			return letter, nil
+		}
+	}
+	return DeadLetter{}, ErrDeadLetterNotFound
+}
+
 type memorySubscription struct {
 	once  sync.Once
 	queue *memoryQueue
@@ -86,3 +288,47 @@ func (s *memorySubscription) Close() {
 		close(s.ch)
 	})
 }
+
+type memoryConsumer struct {
+	queue  *memoryQueue
+	mu     sync.Mutex
+	closed bool
+	ch     chan Delivery
+}
+
+func (c *memoryConsumer) Deliveries() <-chan Delivery {
+	return c.ch
+}
+
+func (c *memoryConsumer) offer(delivery Delivery) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		return false
+	}
+	select {
+	case c.ch <- delivery:
+		return true
+	default:
+		return false
+	}
+}
+
+// Close stops deliveries and drops any still buffered; in-memory events do
+// not outlive their consumer.
+func (c *memoryConsumer) Close() {
+	c.mu.Lock()
+	if c.closed {
+		c.mu.Unlock()
+		return
+	}
+	c.closed = true
+	c.mu.Unlock()
+	c.queue.mu.Lock()
+	delete(c.queue.consumers, c)
+	c.queue.mu.Unlock()
+	close(c.ch)
+	for range c.ch {
+		c.queue.settle(nil)
+	}
+}
@@ -0,0 +1,150 @@
+package chat
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/observability/metrics"
+)
+
+func testEvent(id string) Event {
+	return Event{
+		Type: EventTypeMessage,
+		Message: &MessageEvent{
+			ID:        id,
+			ChannelID: "channel-1",
+			UserID:    "user-1",
+			Content:   "hello",
+			CreatedAt: time.Now().UTC().Truncate(time.Millisecond),
+		},
+		OccurredAt: time.Now().UTC(),
+	}
+}
+
+func nextDelivery(t *testing.T, sub DeliverySubscription) Delivery {
+	t.Helper()
+	select {
+	case delivery, ok := <-sub.Deliveries():
+		if !ok {
+			t.Fatal("delivery channel closed")
+		}
+		return delivery
+	case <-time.After(5 * time.Second):
+		t.Fatal("timed out waiting for delivery")
+	}
+	return Delivery{}
+}
+
+func waitForQueueStats(t *testing.T, recorder *metrics.Recorder, pending, deadLetters int64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		_, gotPending, gotDead := recorder.ChatQueueStats()
+		if gotPending == pending && gotDead == deadLetters {
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected pending=%d dead=%d in metrics, got pending=%d dead=%d", pending, deadLetters, gotPending, gotDead)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestMemoryQueueDeadLettersPoisonEvent(t *testing.T) {
+	recorder := metrics.New()
+	queue := NewMemoryQueueWithConfig(MemoryQueueConfig{
+		Buffer:        4,
+		MaxDeliveries: 3,
+		RetryDelay:    10 * time.Millisecond,
+		Metrics:       recorder,
+	})
+	sub := queue.Consume()
+	defer sub.Close()
+
+	ctx := context.Background()
+	if err := queue.Publish(ctx, testEvent("evt-poison")); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	cause := errors.New("constraint violation")
+	for attempt := 1; attempt <= 3; attempt++ {
+		delivery := nextDelivery(t, sub)
+		if delivery.Attempt != attempt {
+			t.Fatalf("expected attempt %d, got %d", attempt, delivery.Attempt)
+		}
+		if attempt == 1 {
+			waitForQueueStats(t, recorder, 1, 0)
+		}
+		if err := delivery.Fail(cause); err != nil {
+			t.Fatalf("Fail: %v", err)
+		}
+	}
+	select {
+	case delivery := <-sub.Deliveries():
+		t.Fatalf("expected no redelivery after the final attempt, got %+v", delivery)
+	case <-time.After(50 * time.Millisecond):
+	}
+	waitForQueueStats(t, recorder, 0, 1)
+
+	letters, err := queue.ListDeadLetters(ctx, 0)
+	if err != nil {
+		t.Fatalf("ListDeadLetters: %v", err)
+	}
+	if len(letters) != 1 {
+		t.Fatalf("expected 1 dead letter, got %d", len(letters))
+	}
+	letter := letters[0]
+	if letter.Attempts != 3 || letter.Error != cause.Error() || letter.Event.Message == nil || letter.Event.Message.ID != "evt-poison" {
+		t.Fatalf("unexpected dead letter: %+v", letter)
+	}
+	if len(letter.Payload) == 0 {
+		t.Fatal("expected dead letter payload")
+	}
+
+	if err := queue.RequeueDeadLetter(ctx, letter.ID); err != nil {
+		t.Fatalf("RequeueDeadLetter: %v", err)
+	}
+	delivery := nextDelivery(t, sub)
+	if delivery.Attempt != 1 || delivery.Event.Message == nil || delivery.Event.Message.ID != "evt-poison" {
+		t.Fatalf("unexpected requeued delivery: %+v", delivery)
+	}
+	if err := delivery.Ack(); err != nil {
+		t.Fatalf("Ack: %v", err)
+	}
+	waitForQueueStats(t, recorder, 0, 0)
+	if _, err := queue.GetDeadLetter(ctx, letter.ID); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Fatalf("expected requeued dead letter to be removed, got %v", err)
+	}
+}
+
+func TestMemoryQueueDiscardDeadLetter(t *testing.T) {
+	queue := NewMemoryQueueWithConfig(MemoryQueueConfig{MaxDeliveries: 1, Metrics: metrics.New()})
+	sub := queue.Consume()
+	defer sub.Close()
+
+	ctx := context.Background()
+	if err := queue.Publish(ctx, testEvent("evt-discard")); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	if err := nextDelivery(t, sub).Fail(errors.New("boom")); err != nil {
+		t.Fatalf("Fail: %v", err)
+	}
+	letters, err := queue.ListDeadLetters(ctx, 10)
+	if err != nil || len(letters) != 1 {
+		t.Fatalf("expected 1 dead letter, got %d (err=%v)", len(letters), err)
+	}
+	if err := queue.DiscardDeadLetter(ctx, letters[0].ID); err != nil {
+		t.Fatalf("DiscardDeadLetter: %v", err)
+	}
+	if err := queue.DiscardDeadLetter(ctx, letters[0].ID); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Fatalf("expected ErrDeadLetterNotFound, got %v", err)
+	}
+	stats, err := queue.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if stats != (QueueStats{}) {
+		t.Fatalf("expected empty stats, got %+v", stats)
+	}
+}
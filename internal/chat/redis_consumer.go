@@ -0,0 +1,496 @@
+package chat
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Consume starts a consumer that acknowledges each event explicitly. Failed
+// events stay pending in the consumer group and are reclaimed with XAUTOCLAIM
+// once they have been idle for the configured claim threshold; events that
+// exhaust the delivery limit move to the dead-letter stream with their last
+// error attached.
+func (q *redisQueue) Consume() DeliverySubscription {
+	ctx, cancel := context.WithCancel(context.Background())
+	if err := q.ensureGroup(ctx); err != nil {
+		if q.logger != nil {
+			q.logger.Error("redis queue group setup failed", "error", err)
+		}
+	}
+	consumer := &redisConsumer{
+		queue:    q,
+		consumer: randomConsumerID(),
+		cancel:   cancel,
+		ch:       make(chan Delivery, q.buffer),
+	}
+	go consumer.run(ctx)
+	return consumer
+}
+
+type redisConsumer struct {
+	queue    *redisQueue
+	consumer string
+	cancel   context.CancelFunc
+
+	once sync.Once
+	ch   chan Delivery
+}
+
+func (c *redisConsumer) Deliveries() <-chan Delivery {
+	return c.ch
+}
+
+// Close stops the consumer. Deliveries that were not settled stay pending and
+// are reclaimed by another consumer once idle.
+func (c *redisConsumer) Close() {
+	c.once.Do(func() {
+		if c.cancel != nil {
+			c.cancel()
+		}
+	})
+}
+
+func (c *redisConsumer) run(ctx context.Context) {
+	defer close(c.ch)
+	groupBackoff := newBackoff(200*time.Millisecond, 5*time.Second)
+	readBackoff := newBackoff(200*time.Millisecond, 5*time.Second)
+	claimInterval := c.queue.claimIdle / 2
+	if claimInterval < 10*time.Millisecond {
+		claimInterval = 10 * time.Millisecond
+	}
+	var lastClaim time.Time
+	claimCursor := "0-0"
+	for {
+		if ctx.Err() != nil {
+			return
+		}
+		if err := c.queue.ensureGroup(ctx); err != nil {
+			if errors.Is(err, context.Canceled) {
+				return
+			}
+			c.queue.logger.Warn("redis queue group ensure failed", "error", err)
+			if err := groupBackoff.Sleep(ctx); err != nil {
+				return
+			}
+			continue
+		}
+		groupBackoff.Reset()
+
+		var entries []redisStreamEntry
+		var err error
+		if claimCursor != "0-0" || time.Since(lastClaim) >= claimInterval {
+			lastClaim = time.Now()
+			entries, claimCursor, err = c.claim(ctx, claimCursor)
+			if err == nil {
+				c.queue.recordStats(ctx)
+			}
+		}
+		if err == nil && len(entries) == 0 {
+			entries, err = c.queue.readGroup(ctx, c.consumer)
+		}
+		if err != nil {
+			if errors.Is(err, context.Canceled) {
+				return
+			}
+			c.queue.logger.Warn("redis queue read failed", "error", err)
+			claimCursor = "0-0"
+			if err := readBackoff.Sleep(ctx); err != nil {
+				return
+			}
+			continue
+		}
+		readBackoff.Reset()
+		for _, entry := range entries {
+			if !c.deliver(ctx, entry) {
+				return
+			}
+		}
+	}
+}
+
+// claim takes over pending entries that have been idle for longer than the
+// claim threshold, including this consumer's own failed deliveries, and looks
+// up how often each has been delivered.
+func (c *redisConsumer) claim(ctx context.Context, cursor string) ([]redisStreamEntry, string, error) {
+	q := c.queue
+	minIdle := strconv.FormatInt(q.claimIdle.Milliseconds(), 10)
+	reply, err := q.client.Do(ctx, "XAUTOCLAIM", q.stream, q.group, c.consumer, minIdle, cursor, "COUNT", "32")
+	if err != nil {
+		return nil, "0-0", err
+	}
+	parts, ok := reply.([]interface{})
+	if !ok || len(parts) < 2 {
+		return nil, "0-0", fmt.Errorf("unexpected XAUTOCLAIM reply %T", reply)
+	}
+	next, _ := asString(parts[0])
+	if next == "" {
+		next = "0-0"
+	}
+	records, _ := parts[1].([]interface{})
+	entries := parseStreamRecords(records)
+	if len(parts) > 2 {
+		// Redis 7 reports pending entries whose stream record was deleted;
+		// they are already gone from the PEL, so only the error needs
+		// cleaning up.
+		deleted, _ := parts[2].([]interface{})
+		for _, raw := range deleted {
+			if id, ok := asString(raw); ok {
+				q.clearFailure(ctx, id)
+			}
+		}
+	}
+	if len(entries) == 0 {
+		return nil, next, nil
+	}
+	counts, err := q.deliveryCounts(ctx, c.consumer, entries[0].ID, entries[len(entries)-1].ID, len(entries))
+	if err != nil {
+		return nil, "0-0", err
+	}
+	for i := range entries {
+		entries[i].Deliveries = counts[entries[i].ID]
+		if entries[i].Deliveries == 0 {
+			entries[i].Deliveries = 1
+		}
+	}
+	return entries, next, nil
+}
+
+// deliver hands entry to the consumer, dead-lettering it first when it cannot
+// be decoded or has already used up its deliveries. It reports false when the
+// consumer was closed.
+func (c *redisConsumer) deliver(ctx context.Context, entry redisStreamEntry) bool {
+	q := c.queue
+	if entry.Deliveries <= 0 {
+		entry.Deliveries = 1
+	}
+	var event Event
+	if err := json.Unmarshal(entry.Payload, &event); err != nil || len(entry.Payload) == 0 {
+		if err == nil {
+			err = errors.New("entry has no payload")
+		}
+		q.deadLetter(ctx, entry, entry.Deliveries, fmt.Errorf("decode event: %w", err))
+		return true
+	}
+	if entry.Deliveries > q.maxDeliveries {
+		// The previous holder failed or vanished on the final attempt.
+		q.deadLetter(ctx, entry, entry.Deliveries-1, errors.New(q.lastFailure(ctx, entry.ID)))
+		return true
+	}
+	var once sync.Once
+	delivery := Delivery{
+		ID:      entry.ID,
+		Event:   event,
+		Attempt: entry.Deliveries,
+		ack: func() error {
+			var err error
+			once.Do(func() { err = q.ack(entry.ID) })
+			return err
+		},
+		fail: func(cause error) error {
+			var err error
+			once.Do(func() {
+				if entry.Deliveries >= q.maxDeliveries {
+					err = q.deadLetter(context.Background(), entry, entry.Deliveries, cause)
+					return
+				}
+				err = q.recordFailure(entry.ID, cause)
+			})
+			return err
+		},
+	}
+	select {
+	case c.ch <- delivery:
+		return true
+	case <-ctx.Done():
+		return false
+	}
+}
+
+func (q *redisQueue) ack(id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), q.opTimeout())
+	defer cancel()
+	_, err := q.client.Pipeline(ctx,
+		[]interface{}{"XACK", q.stream, q.group, id},
+		[]interface{}{"HDEL", q.failuresKey(), id},
+	)
+	if err != nil {
+		return fmt.Errorf("ack chat event %s: %w", id, err)
+	}
+	return nil
+}
+
+// recordFailure stores the latest error for a pending entry so it travels with
+// the event if it is eventually dead-lettered by another consumer.
+func (q *redisQueue) recordFailure(id string, cause error) error {
+	ctx, cancel := context.WithTimeout(context.Background(), q.opTimeout())
+	defer cancel()
+	if _, err := q.client.Do(ctx, "HSET", q.failuresKey(), id, cause.Error()); err != nil {
+		return fmt.Errorf("record chat event failure %s: %w", id, err)
+	}
+	return nil
+}
+
+func (q *redisQueue) lastFailure(ctx context.Context, id string) string {
+	reply, err := q.client.Do(ctx, "HGET", q.failuresKey(), id)
+	if err == nil {
+		if msg, ok := asString(reply); ok && msg != "" {
+			return msg
+		}
+	}
+	return fmt.Sprintf("exceeded %d deliveries", q.maxDeliveries)
+}
+
+func (q *redisQueue) clearFailure(ctx context.Context, id string) {
+	if _, err := q.client.Do(ctx, "HDEL", q.failuresKey(), id); err != nil {
+		q.logger.Warn("redis failure cleanup failed", "id", id, "error", err)
+	}
+}
+
+// deadLetter moves entry to the dead-letter stream and acknowledges it in one
+// transaction so an event is never both pending and dead-lettered.
+func (q *redisQueue) deadLetter(ctx context.Context, entry redisStreamEntry, attempts int, cause error) error {
+	ctx, cancel := context.WithTimeout(context.WithoutCancel(ctx), q.opTimeout())
+	defer cancel()
+	replies, err := q.client.Pipeline(ctx,
+		[]interface{}{"MULTI"},
+		[]interface{}{"XADD", q.deadLetterStream, "*",
+			"payload", string(entry.Payload),
+			"error", cause.Error(),
+			"attempts", strconv.Itoa(attempts),
+			"source_id", entry.ID,
+			"failed_at", time.Now().UTC().Format(time.RFC3339Nano),
+		},
+		[]interface{}{"XACK", q.stream, q.group, entry.ID},
+		[]interface{}{"HDEL", q.failuresKey(), entry.ID},
+		[]interface{}{"EXEC"},
+	)
+	if err == nil {
+		if len(replies) != 5 {
+			err = fmt.Errorf("unexpected reply count %d", len(replies))
+		} else if _, ok := replies[4].([]interface{}); !ok {
+			err = errors.New("transaction aborted")
+		}
+	}
+	if err != nil {
+		q.logger.Error("redis dead-letter failed", "id", entry.ID, "error", err)
+		return fmt.Errorf("dead-letter chat event %s: %w", entry.ID, err)
+	}
+	q.logger.Warn("chat event dead-lettered", "id", entry.ID, "attempts", attempts, "error", cause)
+	q.recordStats(ctx)
+	return nil
+}
+
+func (q *redisQueue) deliveryCounts(ctx context.Context, consumer, start, end string, count int) (map[string]int, error) {
+	reply, err := q.client.Do(ctx, "XPENDING", q.stream, q.group, start, end, strconv.Itoa(count), consumer)
+	if err != nil {
+		return nil, err
+	}
+	rows, _ := reply.([]interface{})
+	counts := make(map[string]int, len(rows))
+	for _, raw := range rows {
+		row, ok := raw.([]interface{})
+		if !ok || len(row) < 4 {
+			continue
+		}
+		id, _ := asString(row[0])
+		deliveries, err := replyInt(row[3])
+		if id == "" || err != nil {
+			continue
+		}
+		counts[id] = int(deliveries)
+	}
+	return counts, nil
+}
+
+// Stats reports the group's lag as depth, its pending entry count, and the
+// dead-letter stream length.
+func (q *redisQueue) Stats(ctx context.Context) (QueueStats, error) {
+	ctx, cancel := context.WithTimeout(ctx, q.opTimeout())
+	defer cancel()
+	replies, err := q.client.Pipeline(ctx,
+		[]interface{}{"XINFO", "GROUPS", q.stream},
+		[]interface{}{"XLEN", q.deadLetterStream},
+	)
+	if err != nil {
+		return QueueStats{}, fmt.Errorf("chat queue stats: %w", err)
+	}
+	if len(replies) != 2 {
+		return QueueStats{}, fmt.Errorf("chat queue stats: unexpected reply count %d", len(replies))
+	}
+	var stats QueueStats
+	groups, _ := replies[0].([]interface{})
+	for _, raw := range groups {
+		fields, ok := raw.([]interface{})
+		if !ok {
+			continue
+		}
+		values := make(map[string]interface{}, len(fields)/2)
+		for i := 0; i+1 < len(fields); i += 2 {
+			name, _ := asString(fields[i])
+			values[name] = fields[i+1]
+		}
+		if name, _ := asString(values["name"]); name != q.group {
+			continue
+		}
+		stats.Pending, _ = replyInt(values["pending"])
+		stats.Depth, _ = replyInt(values["lag"])
+	}
+	stats.DeadLetters, _ = replyInt(replies[1])
+	return stats, nil
+}
+
+func (q *redisQueue) recordStats(ctx context.Context) {
+	if q.metrics == nil {
+		return
+	}
+	stats, err := q.Stats(ctx)
+	if err != nil {
+		q.logger.Warn("redis queue stats failed", "error", err)
+		return
+	}
+	q.metrics.SetChatQueueStats(stats.Depth, stats.Pending, stats.DeadLetters)
+}
+
+// ListDeadLetters returns up to limit dead-lettered events, oldest first. A
+// non-positive limit returns all of them.
+func (q *redisQueue) ListDeadLetters(ctx context.Context, limit int) ([]DeadLetter, error) {
+	args := []interface{}{"XRANGE", q.deadLetterStream, "-", "+"}
+	if limit > 0 {
+		args = append(args, "COUNT", strconv.Itoa(limit))
+	}
+	reply, err := q.client.Do(ctx, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list dead letters: %w", err)
+	}
+	records, _ := reply.([]interface{})
+	letters := make([]DeadLetter, 0, len(records))
+	for _, record := range records {
+		if letter, ok := parseDeadLetter(record); ok {
+			letters = append(letters, letter)
+		}
+	}
+	return letters, nil
+}
+
+func (q *redisQueue) GetDeadLetter(ctx context.Context, id string) (DeadLetter, error) {
+	reply, err := q.client.Do(ctx, "XRANGE", q.deadLetterStream, id, id)
+	if err != nil {
+		return DeadLetter{}, fmt.Errorf("get dead letter: %w", err)
+	}
+	records, _ := reply.([]interface{})
+	for _, record := range records {
+		if letter, ok := parseDeadLetter(record); ok && letter.ID == id {
+			return letter, nil
+		}
+	}
+	return DeadLetter{}, ErrDeadLetterNotFound
+}
+
+// RequeueDeadLetter appends the dead-lettered payload to the chat stream as a
+// new entry with a fresh delivery count and removes it from the dead-letter
+// stream.
+func (q *redisQueue) RequeueDeadLetter(ctx context.Context, id string) error {
+	letter, err := q.GetDeadLetter(ctx, id)
+	if err != nil {
+		return err
+	}
+	if len(letter.Payload) == 0 {
+		return fmt.Errorf("dead letter %s has no payload", id)
+	}
+	if err := q.ensureGroup(ctx); err != nil {
+		return err
+	}
+	replies, err := q.client.Pipeline(ctx,
+		[]interface{}{"MULTI"},
+		[]interface{}{"XADD", q.stream, "*", "payload", string(letter.Payload)},
+		[]interface{}{"XDEL", q.deadLetterStream, id},
+		[]interface{}{"EXEC"},
+	)
+	if err != nil {
+		return fmt.Errorf("requeue dead letter %s: %w", id, err)
+	}
+	if len(replies) != 4 {
+		return fmt.Errorf("requeue dead letter %s: unexpected reply count %d", id, len(replies))
+	}
+	if _, ok := replies[3].([]interface{}); !ok {
+		return fmt.Errorf("requeue dead letter %s: transaction aborted", id)
+	}
+	q.recordStats(ctx)
+	return nil
+}
+
+func (q *redisQueue) DiscardDeadLetter(ctx context.Context, id string) error {
+	reply, err := q.client.Do(ctx, "XDEL", q.deadLetterStream, id)
+	if err != nil {
+		return fmt.Errorf("discard dead letter %s: %w", id, err)
+	}
+	if removed, _ := replyInt(reply); removed == 0 {
+		return ErrDeadLetterNotFound
+	}
+	q.recordStats(ctx)
+	return nil
+}
+
+func (q *redisQueue) failuresKey() string {
+	return q.stream + ":failures"
+}
+
+func (q *redisQueue) opTimeout() time.Duration {
+	if q.blockTimeout > 0 {
+		return q.blockTimeout + time.Second
+	}
+	return 2 * time.Second
+}
+
+func parseDeadLetter(record interface{}) (DeadLetter, bool) {
+	tuple, ok := record.([]interface{})
+	if !ok || len(tuple) != 2 {
+		return DeadLetter{}, false
+	}
+	id, _ := asString(tuple[0])
+	if id == "" {
+		return DeadLetter{}, false
+	}
+	fields, _ := tuple[1].([]interface{})
+	letter := DeadLetter{ID: id}
+	for i := 0; i+1 < len(fields); i += 2 {
+		name, _ := asString(fields[i])
+		value, _ := asString(fields[i+1])
+		switch name {
+		case "payload":
+			letter.Payload = []byte(value)
+		case "error":
+			letter.Error = value
+		case "attempts":
+			letter.Attempts, _ = strconv.Atoi(value)
+		case "source_id":
+			letter.SourceID = value
+		case "failed_at":
+			letter.FailedAt, _ = time.Parse(time.RFC3339Nano, value)
+		}
+	}
+	if len(letter.Payload) > 0 {
+		_ = json.Unmarshal(letter.Payload, &letter.Event)
+	}
+	return letter, true
+}
+
+func replyInt(value interface{}) (int64, error) {
+	switch v := value.(type) {
+	case int64:
+		return v, nil
+	case string:
+		return strconv.ParseInt(v, 10, 64)
+	case []byte:
+		return strconv.ParseInt(string(v), 10, 64)
+	case nil:
+		return 0, nil
+	default:
+		return 0, fmt.Errorf("unexpected integer reply %T", value)
+	}
+}
@@ -21,6 +21,8 @@ import (
 	"time"
 
 	redis "github.com/redis/go-redis/v9"
+
+	"bitriver-live/internal/observability/metrics"
 )
 
 // RedisTLSConfig controls TLS behaviour for Redis connections.
@@ -33,6 +35,9 @@ type RedisTLSConfig struct {
 }
 
 // RedisQueueConfig configures the Redis-backed chat queue implementation.
+// DeadLetterStream defaults to the stream name with a ":dead" suffix,
+// MaxDeliveries to DefaultMaxDeliveries, ClaimIdle to DefaultRetryDelay, and
+// Metrics to metrics.Default.
 type RedisQueueConfig struct {
 	Addr         string
 	Addrs        []string
@@ -49,11 +54,16 @@ type RedisQueueConfig struct {
 	PoolSize     int
 	MasterName   string
 	TLS          RedisTLSConfig
+
+	DeadLetterStream string
+	MaxDeliveries    int
+	ClaimIdle        time.Duration
+	Metrics          *metrics.Recorder
 }
 
 // NewRedisQueue initialises a queue backed by Redis Streams. The caller is
 // responsible for ensuring the Redis instance is reachable.
-func NewRedisQueue(cfg RedisQueueConfig) (Queue, error) {
+func NewRedisQueue(cfg RedisQueueConfig) (ReliableQueue, error) {
 	addrs := make([]string, 0, len(cfg.Addrs)+1)
 	for _, addr := range cfg.Addrs {
 		if trimmed := strings.TrimSpace(addr); trimmed != "" {
@@ -77,6 +87,19 @@ func NewRedisQueue(cfg RedisQueueConfig) (Queue, error) {
 	if cfg.Buffer <= 0 {
 		cfg.Buffer = 128
 	}
+	deadLetterStream := strings.TrimSpace(cfg.DeadLetterStream)
+	if deadLetterStream == "" {
+		deadLetterStream = stream + ":dead"
+	}
+	if cfg.MaxDeliveries <= 0 {
+		cfg.MaxDeliveries = DefaultMaxDeliveries
+	}
+	if cfg.ClaimIdle <= 0 {
+		cfg.ClaimIdle = DefaultRetryDelay
+	}
+	if cfg.Metrics == nil {
+		cfg.Metrics = metrics.Default()
+	}
 	tlsConfig, err := buildTLSConfig(cfg.TLS)
 	if err != nil {
 		return nil, err
@@ -103,6 +126,11 @@ func NewRedisQueue(cfg RedisQueueConfig) (Queue, error) {
 		blockTimeout: cfg.BlockTimeout,
 		logger:       cfg.Logger,
 		buffer:       cfg.Buffer,
+
+		deadLetterStream: deadLetterStream,
+		maxDeliveries:    cfg.MaxDeliveries,
+		claimIdle:        cfg.ClaimIdle,
+		metrics:          cfg.Metrics,
 	}
 	if queue.logger == nil {
 		queue.logger = slog.Default()
@@ -125,6 +153,11 @@ type redisQueue struct {
 	logger       *slog.Logger
 	buffer       int
 
+	deadLetterStream string
+	maxDeliveries    int
+	claimIdle        time.Duration
+	metrics          *metrics.Recorder
+
 	groupMu    sync.Mutex
 	groupReady atomic.Bool
 }
@@ -267,6 +300,22 @@ func (q *redisQueue) ensureGroup(ctx context.Context) error {
 	return nil
 }
 
+// PingRedisQueue connects to the Redis deployment described by cfg, makes
+// sure the consumer group exists, and sends PING, without keeping the
+// connection. It lets operators validate chat queue settings before the
+// server starts.
+func PingRedisQueue(ctx context.Context, cfg RedisQueueConfig) error {
+	queue, err := NewRedisQueue(cfg)
+	if err != nil {
+		return err
+	}
+	rq := queue.(*redisQueue)
+	defer func() {
+		_ = rq.client.Close()
+	}()
+	return rq.Ping(ctx)
+}
+
 func (q *redisQueue) Ping(ctx context.Context) error {
 	if q == nil || q.client == nil {
 		return nil
@@ -409,24 +458,41 @@ func (s *redisSubscription) requeueEntry(entry redisStreamEntry) {
 }
 
 type redisStreamEntry struct {
-	ID      string
-	Payload []byte
+	ID         string
+	Payload    []byte
+	Deliveries int
 }
 
 func (s *redisSubscription) read(ctx context.Context) ([]redisStreamEntry, error) {
-	blockMs := int(math.Max(float64(s.queue.blockTimeout.Milliseconds()), 1))
-	reply, err := s.queue.client.Do(
+	entries, err := s.queue.readGroup(ctx, s.consumer)
+	if err != nil {
+		return nil, err
+	}
+	filtered := entries[:0]
+	for _, entry := range entries {
+		if len(entry.Payload) > 0 {
+			filtered = append(filtered, entry)
+		}
+	}
+	return filtered, nil
+}
+
+// readGroup reads new entries for consumer. Entries without a payload are
+// returned so reliable consumers can dead-letter them.
+func (q *redisQueue) readGroup(ctx context.Context, consumer string) ([]redisStreamEntry, error) {
+	blockMs := int(math.Max(float64(q.blockTimeout.Milliseconds()), 1))
+	reply, err := q.client.Do(
 		ctx,
 		"XREADGROUP",
 		"GROUP",
-		s.queue.group,
-		s.consumer,
+		q.group,
+		consumer,
 		"COUNT",
 		"32",
 		"BLOCK",
 		strconv.Itoa(blockMs),
 		"STREAMS",
-		s.queue.stream,
+		q.stream,
 		">",
 	)
 	if err != nil {
@@ -446,23 +512,28 @@ func (s *redisSubscription) read(ctx context.Context) ([]redisStreamEntry, error
 			continue
 		}
 		records, _ := parts[1].([]interface{})
-		for _, record := range records {
-			tuple, ok := record.([]interface{})
-			if !ok || len(tuple) != 2 {
-				continue
-			}
-			id, _ := asString(tuple[0])
-			fields, _ := tuple[1].([]interface{})
-			payload := extractPayload(fields)
-			if id == "" || len(payload) == 0 {
-				continue
-			}
-			entries = append(entries, redisStreamEntry{ID: id, Payload: payload})
-		}
+		entries = append(entries, parseStreamRecords(records)...)
 	}
 	return entries, nil
 }
 
+func parseStreamRecords(records []interface{}) []redisStreamEntry {
+	var entries []redisStreamEntry
+	for _, record := range records {
+		tuple, ok := record.([]interface{})
+		if !ok || len(tuple) != 2 {
+			continue
+		}
+		id, _ := asString(tuple[0])
+		if id == "" {
+			continue
+		}
+		fields, _ := tuple[1].([]interface{})
+		entries = append(entries, redisStreamEntry{ID: id, Payload: extractPayload(fields), Deliveries: 1})
+	}
+	return entries
+}
+
 func extractPayload(fields []interface{}) []byte {
 	for i := 0; i < len(fields); i += 2 {
 		key, _ := asString(fields[i])
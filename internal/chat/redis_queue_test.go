@@ -11,6 +11,7 @@ import (
 
 	redis "github.com/redis/go-redis/v9"
 
+	"bitriver-live/internal/observability/metrics"
 	"bitriver-live/internal/testsupport/redisstub"
 )
 
@@ -467,6 +468,10 @@ func (c *flakyGroupClient) Do(ctx context.Context, args ...interface{}) (interfa
 	return c.delegate.Do(ctx, args...)
 }
 
+func (c *flakyGroupClient) Pipeline(ctx context.Context, cmds ...[]interface{}) ([]interface{}, error) {
+	return c.delegate.Pipeline(ctx, cmds...)
+}
+
 func (c *flakyGroupClient) shouldFail(args []interface{}) bool {
 	if len(args) == 0 {
 		return false
@@ -531,3 +536,135 @@ func (c *failingXAddClient) AckIDs() []string {
 	copy(ids, c.acks)
 	return ids
 }
+
+func newReliableTestQueue(t *testing.T, recorder *metrics.Recorder) *redisQueue {
+	t.Helper()
+	srv, err := redisstub.Start(redisstub.Options{Password: "secret"})
+	if err != nil {
+		t.Fatalf("failed to start redis stub: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = srv.Close()
+	})
+	queue, err := NewRedisQueue(RedisQueueConfig{
+		Addr:          srv.Addr(),
+		Password:      "secret",
+		Stream:        "test-stream",
+		Group:         "test-group",
+		BlockTimeout:  20 * time.Millisecond,
+		Buffer:        4,
+		MaxDeliveries: 3,
+		ClaimIdle:     30 * time.Millisecond,
+		Metrics:       recorder,
+	})
+	if err != nil {
+		t.Fatalf("create queue: %v", err)
+	}
+	return queue.(*redisQueue)
+}
+
+func TestRedisQueueDeadLettersPoisonEvent(t *testing.T) {
+	recorder := metrics.New()
+	queue := newReliableTestQueue(t, recorder)
+	sub := queue.Consume()
+	defer sub.Close()
+
+	ctx := context.Background()
+	if err := queue.Publish(ctx, testEvent("evt-poison")); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	cause := errors.New("constraint violation")
+	for attempt := 1; attempt <= 3; attempt++ {
+		delivery := nextDelivery(t, sub)
+		if delivery.Attempt != attempt {
+			t.Fatalf("expected attempt %d, got %d", attempt, delivery.Attempt)
+		}
+		if delivery.Event.Message == nil || delivery.Event.Message.ID != "evt-poison" {
+			t.Fatalf("unexpected delivery: %+v", delivery)
+		}
+		if attempt < 3 {
+			waitForQueueStats(t, recorder, 1, 0)
+		}
+		if err := delivery.Fail(cause); err != nil {
+			t.Fatalf("Fail: %v", err)
+		}
+	}
+	waitForQueueStats(t, recorder, 0, 1)
+
+	letters, err := queue.ListDeadLetters(ctx, 10)
+	if err != nil {
+		t.Fatalf("ListDeadLetters: %v", err)
+	}
+	if len(letters) != 1 {
+		t.Fatalf("expected 1 dead letter, got %d", len(letters))
+	}
+	letter := letters[0]
+	if letter.Attempts != 3 || letter.Error != cause.Error() || letter.SourceID == "" {
+		t.Fatalf("unexpected dead letter: %+v", letter)
+	}
+	if letter.Event.Message == nil || letter.Event.Message.ID != "evt-poison" {
+		t.Fatalf("expected dead letter to carry the event, got %+v", letter.Event)
+	}
+	got, err := queue.GetDeadLetter(ctx, letter.ID)
+	if err != nil || got.SourceID != letter.SourceID {
+		t.Fatalf("GetDeadLetter: %+v err=%v", got, err)
+	}
+
+	// Simulate the underlying issue being fixed: the requeued event is
+	// delivered fresh and acknowledged.
+	if err := queue.RequeueDeadLetter(ctx, letter.ID); err != nil {
+		t.Fatalf("RequeueDeadLetter: %v", err)
+	}
+	delivery := nextDelivery(t, sub)
+	if delivery.Attempt != 1 || delivery.Event.Message == nil || delivery.Event.Message.ID != "evt-poison" {
+		t.Fatalf("unexpected requeued delivery: %+v", delivery)
+	}
+	if err := delivery.Ack(); err != nil {
+		t.Fatalf("Ack: %v", err)
+	}
+	stats, err := queue.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if stats.Pending != 0 || stats.DeadLetters != 0 {
+		t.Fatalf("expected drained queue after requeue, got %+v", stats)
+	}
+	waitForQueueStats(t, recorder, 0, 0)
+	if _, err := queue.GetDeadLetter(ctx, letter.ID); !errors.Is(err, ErrDeadLetterNotFound) {
+		t.Fatalf("expected requeued dead letter to be removed, got %v", err)
+	}
+}
+
+func TestRedisQueueDeadLettersUndecodableEntry(t *testing.T) {
+	queue := newReliableTestQueue(t, metrics.New())
+	ctx := context.Background()
+	if _, err := queue.client.Do(ctx, "XADD", queue.stream, "*", "payload", "{not json"); err != nil {
+		t.Fatalf("XADD: %v", err)
+	}
+	sub := queue.Consume()
+	defer sub.Close()
+
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		letters, err := queue.ListDeadLetters(ctx, 0)
+		if err != nil {
+			t.Fatalf("ListDeadLetters: %v", err)
+		}
+		if len(letters) == 1 {
+			if !strings.Contains(letters[0].Error, "decode event") || string(letters[0].Payload) != "{not json" {
+				t.Fatalf("unexpected dead letter: %+v", letters[0])
+			}
+			if err := queue.DiscardDeadLetter(ctx, letters[0].ID); err != nil {
+				t.Fatalf("DiscardDeadLetter: %v", err)
+			}
+			if err := queue.DiscardDeadLetter(ctx, letters[0].ID); !errors.Is(err, ErrDeadLetterNotFound) {
+				t.Fatalf("expected ErrDeadLetterNotFound, got %v", err)
+			}
+			return
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("timed out waiting for undecodable entry to be dead-lettered")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,223 @@
+package chat_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/chat"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+// startResumableGateway serves a gateway over store with a persistence worker
+// and returns it with its WebSocket URL.
+func startResumableGateway(t *testing.T, store *storage.Storage) (*chat.Gateway, string) {
+	t.Helper()
+	queue := chat.NewMemoryQueue(512)
+	gateway := chat.NewGateway(chat.GatewayConfig{Queue: queue, Store: store})
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+	started := make(chan struct{})
+	go storage.NewChatWorker(store, queue, nil).WithStartedChannel(started).Run(ctx)
+	<-started
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		user, ok := store.GetUser(r.URL.Query().Get("user"))
+		if !ok {
+			http.Error(w, "unknown user", http.StatusUnauthorized)
+			return
+		}
+		gateway.HandleConnection(w, r, user)
+	}))
+	t.Cleanup(server.Close)
+	return gateway, strings.Replace(server.URL, "http", "ws", 1)
+}
+
+// joinChannel connects user to the channel, resuming after lastSeq when it is
+// non-negative, and returns the connection once the join is acknowledged.
+func joinChannel(t *testing.T, url string, user models.User, channelID string, lastSeq int64) *chat.Conn {
+	t.Helper()
+	conn := mustDial(t, url+"?user="+user.ID)
+	t.Cleanup(func() { _ = conn.Close() })
+	join := map[string]any{"type": "join", "channelId": channelID}
+	if lastSeq >= 0 {
+		join["lastSeq"] = lastSeq
+	}
+	sendJSON(t, conn, join)
+	waitForType(t, conn, "ack")
+	return conn
+}
+
+func eventSeq(event map[string]any) int64 {
+	seq, _ := event["seq"].(float64)
+	return int64(seq)
+}
+
+func backfillSeqs(t *testing.T, backfill map[string]any) []int64 {
+	t.Helper()
+	events, ok := backfill["events"].([]any)
+	if !ok {
+		t.Fatalf("expected a backfill event list, got %v", backfill)
+	}
+	seqs := make([]int64, 0, len(events))
+	for _, raw := range events {
+		seqs = append(seqs, eventSeq(raw.(map[string]any)))
+	}
+	return seqs
+}
+
+func postMessages(t *testing.T, gateway *chat.Gateway, author models.User, channelID string, count int) {
+	t.Helper()
+	for i := 0; i < count; i++ {
+		if _, err := gateway.CreateMessage(context.Background(), author, channelID, fmt.Sprintf("message %d", i), ""); err != nil {
+			t.Errorf("CreateMessage: %v", err)
+			return
+		}
+	}
+}
+
+func waitForStoredMessages(t *testing.T, store *storage.Storage, channelID string, count int) {
+	t.Helper()
+	waitUntil(t, 5*time.Second, func() bool {
+		messages, err := store.ListChatMessages(channelID, 0)
+		return err == nil && len(messages) == count
+	})
+}
+
+func TestGatewayResumeIsGapFreeAcrossReconnect(t *testing.T) {
+	store := newTestStorage(t)
+	owner := mustCreateUser(t, store, storage.CreateUserParams{DisplayName: "owner", Email: "owner@example.com", Roles: []string{"admin"}})
+	viewer := mustCreateUser(t, store, storage.CreateUserParams{DisplayName: "viewer", Email: "viewer@example.com"})
+	channel := mustCreateChannel(t, store, owner.ID, "Main")
+	gateway, url := startResumableGateway(t, store)
+
+	conn := joinChannel(t, url, viewer, channel.ID, -1)
+	postMessages(t, gateway, owner, channel.ID, 3)
+	var lastSeq int64
+	for lastSeq < 3 {
+		event := waitForType(t, conn, "event")["event"].(map[string]any)
+		if seq := eventSeq(event); seq != lastSeq+1 {
+			t.Fatalf("expected live event %d, got %d", lastSeq+1, seq)
+		}
+		lastSeq++
+	}
+	_ = conn.Close()
+
+	// Messages keep arriving while the viewer is away and while it rejoins.
+	postMessages(t, gateway, owner, channel.ID, 5)
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for i := 0; i < 20; i++ {
+			postMessages(t, gateway, owner, channel.ID, 1)
+			time.Sleep(2 * time.Millisecond)
+		}
+	}()
+	conn = joinChannel(t, url, viewer, channel.ID, lastSeq)
+	backfill := waitForType(t, conn, "backfill")
+	if backfill["overflow"] == true {
+		t.Fatalf("expected no overflow, got %v", backfill)
+	}
+	received := backfillSeqs(t, backfill)
+	<-done
+	for len(received) == 0 || received[len(received)-1] < 28 {
+		event := waitForType(t, conn, "event")["event"].(map[string]any)
+		received = append(received, eventSeq(event))
+	}
+	for i, seq := range received {
+		if seq != lastSeq+int64(i)+1 {
+			t.Fatalf("expected every event after %d exactly once and in order, got %v", lastSeq, received)
+		}
+	}
+}
+
+func TestGatewayResumeSignalsOverflow(t *testing.T) {
+	store := newTestStorage(t)
+	owner := mustCreateUser(t, store, storage.CreateUserParams{DisplayName: "owner", Email: "owner@example.com", Roles: []string{"admin"}})
+	viewer := mustCreateUser(t, store, storage.CreateUserParams{DisplayName: "viewer", Email: "viewer@example.com"})
+	channel := mustCreateChannel(t, store, owner.ID, "Main")
+	gateway, url := startResumableGateway(t, store)
+
+	total := chat.MaxBackfillEvents + 1
+	postMessages(t, gateway, owner, channel.ID, total)
+	waitForStoredMessages(t, store, channel.ID, total)
+
+	conn := joinChannel(t, url, viewer, channel.ID, 0)
+	backfill := waitForType(t, conn, "backfill")
+	if backfill["overflow"] != true || len(backfillSeqs(t, backfill)) != 0 || backfill["lastSeq"] != float64(total) {
+		t.Fatalf("expected an overflow pointing at seq %d, got %v", total, backfill)
+	}
+	postMessages(t, gateway, owner, channel.ID, 1)
+	if event := waitForType(t, conn, "event")["event"].(map[string]any); eventSeq(event) != int64(total)+1 {
+		t.Fatalf("expected live delivery to continue after the overflow, got %v", event)
+	}
+
+	// A viewer within the window is backfilled normally.
+	recent := joinChannel(t, url, viewer, channel.ID, int64(total)-1)
+	if seqs := backfillSeqs(t, waitForType(t, recent, "backfill")); len(seqs) != 2 || seqs[0] != int64(total) {
+		t.Fatalf("expected the last two events, got %v", seqs)
+	}
+}
+
+func TestGatewaySequencesSurviveRestartAndBackfillModeration(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	store, err := storage.NewStorage(path)
+	if err != nil {
+		t.Fatalf("NewStorage: %v", err)
+	}
+	owner := mustCreateUser(t, store, storage.CreateUserParams{DisplayName: "owner", Email: "owner@example.com", Roles: []string{"admin"}})
+	viewer := mustCreateUser(t, store, storage.CreateUserParams{DisplayName: "viewer", Email: "viewer@example.com"})
+	channel := mustCreateChannel(t, store, owner.ID, "Main")
+	gateway, _ := startResumableGateway(t, store)
+
+	first, err := gateway.CreateMessage(context.Background(), viewer, channel.ID, "first", "")
+	if err != nil {
+		t.Fatalf("CreateMessage: %v", err)
+	}
+	postMessages(t, gateway, viewer, channel.ID, 1)
+	if err := gateway.ApplyModeration(context.Background(), owner, chat.ModerationEvent{Action: chat.ModerationActionDeleteMessage, ChannelID: channel.ID, ActorID: owner.ID, MessageID: first.ID}); err != nil {
+		t.Fatalf("ApplyModeration delete: %v", err)
+	}
+	if err := gateway.ApplyModeration(context.Background(), owner, chat.ModerationEvent{Action: chat.ModerationActionBan, ChannelID: channel.ID, ActorID: owner.ID, TargetID: viewer.ID, Reason: "spam"}); err != nil {
+		t.Fatalf("ApplyModeration ban: %v", err)
+	}
+	waitForStoredMessages(t, store, channel.ID, 1)
+	waitUntil(t, 5*time.Second, func() bool { return store.IsChatBanned(channel.ID, viewer.ID) })
+
+	// A new process starts from the same file with an empty gateway.
+	restarted, err := storage.NewStorage(path)
+	if err != nil {
+		t.Fatalf("reopen storage: %v", err)
+	}
+	gateway, url := startResumableGateway(t, restarted)
+	conn := joinChannel(t, url, owner, channel.ID, 1)
+	backfill := waitForType(t, conn, "backfill")
+	if seqs := backfillSeqs(t, backfill); len(seqs) != 3 || seqs[0] != 2 || seqs[2] != 4 || backfill["lastSeq"] != float64(4) {
+		t.Fatalf("expected stored events 2 to 4 after the restart, got %v", backfill)
+	}
+	events := backfill["events"].([]any)
+	message := events[0].(map[string]any)["message"].(map[string]any)
+	if author, ok := message["author"].(map[string]any); !ok || author["displayName"] != "viewer" {
+		t.Fatalf("expected the backfilled message to carry its author, got %v", message)
+	}
+	deletion := events[1].(map[string]any)["moderation"].(map[string]any)
+	if deletion["action"] != string(chat.ModerationActionDeleteMessage) || deletion["messageId"] != first.ID {
+		t.Fatalf("expected the missed deletion in the backfill, got %v", deletion)
+	}
+	if ban := events[2].(map[string]any)["moderation"].(map[string]any); ban["action"] != string(chat.ModerationActionBan) || ban["targetId"] != viewer.ID {
+		t.Fatalf("expected the missed ban in the backfill, got %v", ban)
+	}
+
+	if _, err := gateway.CreateMessage(context.Background(), owner, channel.ID, "after restart", ""); err != nil {
+		t.Fatalf("CreateMessage: %v", err)
+	}
+	if event := waitForType(t, conn, "event")["event"].(map[string]any); eventSeq(event) != 5 {
+		t.Fatalf("expected numbering to continue at 5 after the restart, got %v", event)
+	}
+}
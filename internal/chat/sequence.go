@@ -0,0 +1,102 @@
+package chat
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	// MaxBackfillEvents caps how many missed events a resuming client is
+	// sent; a client further behind is told to reload the chat history.
+	MaxBackfillEvents = 200
+	// MaxBackfillAge caps how old the first missed event may be before a
+	// resuming client is told to reload the chat history instead.
+	MaxBackfillAge = 15 * time.Minute
+)
+
+// SequenceStore issues and replays per-channel chat sequence numbers. The
+// counter lives in the store so numbers keep increasing across restarts.
+type SequenceStore interface {
+	// NextChatSequence reserves and returns the channel's next sequence
+	// number.
+	NextChatSequence(channelID string) (int64, error)
+	// ChatEventsSince returns up to limit of the channel's persisted message
+	// and moderation events numbered above afterSeq, in sequence order.
+	// Returned message events carry the author's ID only.
+	ChatEventsSince(channelID string, afterSeq int64, limit int) ([]Event, error)
+}
+
+// sequencer serializes sequence assignment and delivery for one channel and
+// keeps its most recent sequenced events. The recent events cover the gap
+// between broadcasting an event and the persistence worker storing it.
+type sequencer struct {
+	mu     sync.Mutex
+	recent []Event
+}
+
+// remember appends evt to the recent events, dropping the oldest past
+// MaxBackfillEvents. Callers must hold s.mu.
+func (s *sequencer) remember(evt Event) {
+	if len(s.recent) >= MaxBackfillEvents {
+		s.recent = s.recent[1:]
+	}
+	s.recent = append(s.recent, evt)
+}
+
+// since returns the recent events numbered above afterSeq and the highest
+// number issued. complete reports whether the recent events reach back to
+// afterSeq, so nothing older needs to be read from the store.
+func (s *sequencer) since(afterSeq int64) (events []Event, head int64, complete bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if len(s.recent) == 0 {
+		return nil, 0, false
+	}
+	head = s.recent[len(s.recent)-1].Seq
+	for _, evt := range s.recent {
+		if evt.Seq > afterSeq {
+			events = append(events, evt)
+		}
+	}
+	return events, head, s.recent[0].Seq <= afterSeq+1
+}
+
+// sequencers hands out the per-channel sequencers.
+type sequencers struct {
+	mu       sync.Mutex
+	channels map[string]*sequencer
+}
+
+func (s *sequencers) get(channelID string) *sequencer {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.channels == nil {
+		s.channels = make(map[string]*sequencer)
+	}
+	seq, ok := s.channels[channelID]
+	if !ok {
+		seq = &sequencer{}
+		s.channels[channelID] = seq
+	}
+	return seq
+}
+
+// mergeSequenced combines stored and recent events, dropping duplicates and
+// ordering them by sequence number.
+func mergeSequenced(stored, recent []Event) []Event {
+	bySeq := make(map[int64]Event, len(stored)+len(recent))
+	for _, evt := range stored {
+		bySeq[evt.Seq] = evt
+	}
+	// Recent events win: their message events already carry the author.
+	for _, evt := range recent {
+		bySeq[evt.Seq] = evt
+	}
+	merged := make([]Event, 0, len(bySeq))
+	for _, evt := range bySeq {
+		merged = append(merged, evt)
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Seq < merged[j].Seq })
+	return merged
+}
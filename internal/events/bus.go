@@ -0,0 +1,197 @@
+package events
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+)
+
+const defaultQueueSize = 256
+
+// Config tunes a Bus. Zero values fall back to the package defaults.
+type Config struct {
+	// QueueSize caps how many events each async subscriber may have
+	// waiting. Events beyond it are dropped for that subscriber.
+	QueueSize int
+	Logger    *slog.Logger
+}
+
+// Bus delivers published events to the subscribers registered for their
+// type. A nil *Bus publishes nothing.
+type Bus struct {
+	queueSize int
+	logger    *slog.Logger
+	wg        sync.WaitGroup
+
+	mu          sync.RWMutex
+	closed      bool
+	subscribers []*subscriber
+}
+
+type subscriber struct {
+	name    string
+	accepts func(Event) bool
+	handle  func(Event)
+	// queue feeds an async subscriber's worker. It is nil for synchronous
+	// subscribers.
+	queue chan Event
+}
+
+// NewBus returns a bus with no subscribers.
+func NewBus(cfg Config) *Bus {
+	b := &Bus{queueSize: cfg.QueueSize, logger: cfg.Logger}
+	if b.queueSize <= 0 {
+		b.queueSize = defaultQueueSize
+	}
+	if b.logger == nil {
+		b.logger = slog.Default()
+	}
+	return b
+}
+
+// SubscribeOption adjusts how a subscriber receives events.
+type SubscribeOption func(*subscribeOptions)
+
+type subscribeOptions struct {
+	async bool
+}
+
+// Async delivers events to the subscriber on its own worker instead of on
+// the publishing goroutine, so slow work such as network calls never
+// delays the request that made the change.
+func Async() SubscribeOption {
+	return func(o *subscribeOptions) {
+		o.async = true
+	}
+}
+
+// Subscribe registers fn for every published event of the concrete type E.
+// name identifies the subscriber in logs. Subscribers should be registered
+// while the server is wired up; those added after Shutdown are ignored.
+func Subscribe[E Event](b *Bus, name string, fn func(E), opts ...SubscribeOption) {
+	var options subscribeOptions
+	for _, opt := range opts {
+		opt(&options)
+	}
+	sub := &subscriber{
+		name: name,
+		accepts: func(event Event) bool {
+			_, ok := event.(E)
+			return ok
+		},
+		handle: func(event Event) {
+			fn(event.(E))
+		},
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.closed {
+		b.logger.Warn("event subscriber registered after shutdown", "subscriber", name)
+		return
+	}
+	if options.async {
+		sub.queue = make(chan Event, b.queueSize)
+		b.wg.Add(1)
+		go b.work(sub)
+	}
+	b.subscribers = append(b.subscribers, sub)
+}
+
+// HasSubscribers reports whether any subscriber receives events of type E,
+// so publishers can skip building an event nobody listens for.
+func HasSubscribers[E Event](b *Bus) bool {
+	if b == nil {
+		return false
+	}
+	var zero E
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, sub := range b.subscribers {
+		if sub.accepts(zero) {
+			return true
+		}
+	}
+	return false
+}
+
+// Publish delivers event to its synchronous subscribers before returning
+// and queues it for its async ones. Callers publish only after the change
+// the event describes has been committed.
+func (b *Bus) Publish(event Event) {
+	if b == nil || event == nil {
+		return
+	}
+	b.mu.RLock()
+	subscribers := b.subscribers
+	b.mu.RUnlock()
+	for _, sub := range subscribers {
+		if !sub.accepts(event) {
+			continue
+		}
+		if sub.queue == nil {
+			b.deliver(sub, event)
+			continue
+		}
+		b.enqueue(sub, event)
+	}
+}
+
+func (b *Bus) enqueue(sub *subscriber, event Event) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.closed {
+		return
+	}
+	select {
+	case sub.queue <- event:
+	default:
+		b.logger.Warn("event subscriber backlog is full, dropping event", "subscriber", sub.name, "event", event.EventName())
+	}
+}
+
+// deliver runs one subscriber, containing any panic so it cannot reach the
+// publisher or the other subscribers.
+func (b *Bus) deliver(sub *subscriber, event Event) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			b.logger.Error("event subscriber panicked", "subscriber", sub.name, "event", event.EventName(), "panic", recovered)
+		}
+	}()
+	sub.handle(event)
+}
+
+func (b *Bus) work(sub *subscriber) {
+	defer b.wg.Done()
+	for event := range sub.queue {
+		b.deliver(sub, event)
+	}
+}
+
+// Shutdown stops queueing events for async subscribers and waits until
+// they have handled the ones already queued or ctx is done. Synchronous
+// subscribers keep receiving events.
+func (b *Bus) Shutdown(ctx context.Context) error {
+	b.mu.Lock()
+	if !b.closed {
+		b.closed = true
+		for _, sub := range b.subscribers {
+			if sub.queue != nil {
+				close(sub.queue)
+			}
+		}
+	}
+	b.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		b.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
@@ -0,0 +1,182 @@
+package events
+
+import (
+	"context"
+	"io"
+	"log/slog"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/models"
+)
+
+func newTestBus(cfg Config) *Bus {
+	if cfg.Logger == nil {
+		cfg.Logger = slog.New(slog.NewTextHandler(io.Discard, nil))
+	}
+	return NewBus(cfg)
+}
+
+func started(id string) StreamStarted {
+	return StreamStarted{Session: models.StreamSession{ID: id}}
+}
+
+func TestSyncSubscribersRunInRegistrationOrderBeforePublishReturns(t *testing.T) {
+	bus := newTestBus(Config{})
+	var calls []string
+	Subscribe(bus, "first", func(event StreamStarted) { calls = append(calls, "first:"+event.Session.ID) })
+	Subscribe(bus, "other-type", func(UserCreated) { calls = append(calls, "user") })
+	Subscribe(bus, "second", func(event StreamStarted) { calls = append(calls, "second:"+event.Session.ID) })
+
+	bus.Publish(started("a"))
+	bus.Publish(started("b"))
+
+	if got := strings.Join(calls, ","); got != "first:a,second:a,first:b,second:b" {
+		t.Fatalf("expected registration order per event, got %s", got)
+	}
+}
+
+func TestAsyncSubscriberReceivesEventsInPublishOrder(t *testing.T) {
+	bus := newTestBus(Config{QueueSize: 100})
+	var (
+		mu  sync.Mutex
+		ids []string
+	)
+	release := make(chan struct{})
+	Subscribe(bus, "slow", func(event StreamStarted) {
+		<-release
+		mu.Lock()
+		ids = append(ids, event.Session.ID)
+		mu.Unlock()
+	}, Async())
+
+	published := make(chan struct{})
+	go func() {
+		for _, id := range []string{"1", "2", "3", "4", "5"} {
+			bus.Publish(started(id))
+		}
+		close(published)
+	}()
+	select {
+	case <-published:
+	case <-time.After(time.Second):
+		t.Fatal("expected Publish not to wait for an async subscriber")
+	}
+	close(release)
+
+	if err := bus.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if got := strings.Join(ids, ","); got != "1,2,3,4,5" {
+		t.Fatalf("expected events in publish order, got %s", got)
+	}
+}
+
+func TestPanickingSubscribersAreIsolated(t *testing.T) {
+	bus := newTestBus(Config{})
+	var (
+		mu        sync.Mutex
+		delivered []string
+	)
+	record := func(name string) {
+		mu.Lock()
+		delivered = append(delivered, name)
+		mu.Unlock()
+	}
+	Subscribe(bus, "sync-panic", func(StreamStarted) { panic("sync boom") })
+	Subscribe(bus, "async-panic", func(event StreamStarted) {
+		if event.Session.ID == "1" {
+			panic("async boom")
+		}
+		record("async-panic:" + event.Session.ID)
+	}, Async())
+	Subscribe(bus, "sync-after", func(event StreamStarted) { record("sync-after:" + event.Session.ID) })
+
+	bus.Publish(started("1"))
+	bus.Publish(started("2"))
+	if err := bus.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+
+	got := strings.Join(delivered, ",")
+	for _, want := range []string{"sync-after:1", "sync-after:2", "async-panic:2"} {
+		if !strings.Contains(got, want) {
+			t.Fatalf("expected %s to be delivered despite panics, got %s", want, got)
+		}
+	}
+}
+
+func TestAsyncBacklogDropsInsteadOfBlocking(t *testing.T) {
+	bus := newTestBus(Config{QueueSize: 1})
+	release := make(chan struct{})
+	handled := make(chan string, 10)
+	Subscribe(bus, "stuck", func(event StreamStarted) {
+		<-release
+		handled <- event.Session.ID
+	}, Async())
+
+	bus.Publish(started("1"))
+	// Wait for the worker to pick up the first event so the second fills
+	// the queue.
+	deadline := time.Now().Add(time.Second)
+	for len(bus.subscribers[0].queue) != 0 && time.Now().Before(deadline) {
+		time.Sleep(time.Millisecond)
+	}
+	bus.Publish(started("2"))
+	bus.Publish(started("3"))
+	close(release)
+	if err := bus.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	close(handled)
+
+	var ids []string
+	for id := range handled {
+		ids = append(ids, id)
+	}
+	if got := strings.Join(ids, ","); got != "1,2" {
+		t.Fatalf("expected the event beyond the backlog to be dropped, got %s", got)
+	}
+}
+
+func TestShutdownStopsAsyncDeliveryAndHonoursContext(t *testing.T) {
+	bus := newTestBus(Config{})
+	block := make(chan struct{})
+	Subscribe(bus, "blocked", func(StreamStarted) { <-block }, Async())
+	var syncCalls int
+	Subscribe(bus, "sync", func(StreamStarted) { syncCalls++ })
+
+	bus.Publish(started("1"))
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+	defer cancel()
+	if err := bus.Shutdown(ctx); err == nil {
+		t.Fatal("expected Shutdown to give up while a subscriber is blocked")
+	}
+	close(block)
+
+	bus.Publish(started("2"))
+	if syncCalls != 2 {
+		t.Fatalf("expected sync subscribers to keep receiving events after shutdown, got %d calls", syncCalls)
+	}
+	Subscribe(bus, "late", func(StreamStarted) { t.Fatal("expected a late subscriber to be ignored") })
+	bus.Publish(started("3"))
+}
+
+func TestHasSubscribersAndNilBus(t *testing.T) {
+	bus := newTestBus(Config{})
+	if HasSubscribers[RecordingCreated](bus) {
+		t.Fatal("expected no subscribers on a new bus")
+	}
+	Subscribe(bus, "recordings", func(RecordingCreated) {}, Async())
+	if !HasSubscribers[RecordingCreated](bus) || HasSubscribers[RecordingPublished](bus) {
+		t.Fatal("expected HasSubscribers to match only the subscribed type")
+	}
+
+	var none *Bus
+	none.Publish(started("1"))
+	if HasSubscribers[StreamStarted](none) {
+		t.Fatal("expected a nil bus to have no subscribers")
+	}
+}
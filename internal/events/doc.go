@@ -0,0 +1,22 @@
+// Package events lets features react to domain changes without editing the
+// repository methods that make them.
+//
+// A Bus carries typed events such as StreamStarted or ChannelFollowed.
+// storage.NewEventRepository publishes them once the wrapped repository call
+// has returned successfully, so a subscriber never sees a change that was
+// rolled back. Subscribers are registered while the server is wired up in
+// cmd/server.
+//
+// Ordering guarantees:
+//   - Synchronous subscribers run on the publishing goroutine, in the order
+//     they subscribed, before the repository call returns to its caller.
+//   - Each asynchronous subscriber has its own worker and receives events one
+//     at a time in the order they were published. Different async
+//     subscribers run independently of each other and of the publisher.
+//   - Events published concurrently by different requests have no defined
+//     order relative to each other.
+//
+// A subscriber that panics is logged and skipped; the publisher and the other
+// subscribers carry on. An async subscriber whose backlog is full drops the
+// event rather than slowing the request down.
+package events
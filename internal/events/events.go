@@ -0,0 +1,57 @@
+package events
+
+import "bitriver-live/internal/models"
+
+// Event is a committed domain change.
+type Event interface {
+	// EventName identifies the event in logs, such as "stream.started".
+	EventName() string
+}
+
+// StreamStarted is published when a channel goes live.
+type StreamStarted struct {
+	Session models.StreamSession
+}
+
+// StreamStopped is published when a live session ends, whichever path
+// stopped it.
+type StreamStopped struct {
+	Session models.StreamSession
+}
+
+// RecordingCreated is published when a stopped session leaves a recording.
+// It follows the session's StreamStopped event.
+type RecordingCreated struct {
+	Recording models.Recording
+}
+
+// RecordingPublished is published when a recording is made public.
+type RecordingPublished struct {
+	Recording models.Recording
+}
+
+// RecordingPremiered is published when a scheduled premiere starts. It
+// follows the recording's RecordingPublished event.
+type RecordingPremiered struct {
+	Recording models.Recording
+}
+
+// ChannelFollowed is published when a user starts following a channel.
+// Following a channel the user already follows publishes nothing.
+type ChannelFollowed struct {
+	UserID    string
+	ChannelID string
+}
+
+// UserCreated is published when an account is created.
+type UserCreated struct {
+	User models.User
+}
+
+func (StreamStarted) EventName() string      { return "stream.started" }
+func (StreamStopped) EventName() string      { return "stream.stopped" }
+func (RecordingCreated) EventName() string   { return "recording.created" }
+func (RecordingPublished) EventName() string { return "recording.published" }
+func (RecordingPremiered) EventName() string { return "recording.premiered" }
+func (ChannelFollowed) EventName() string    { return "channel.followed" }
+func (UserCreated) EventName() string        { return "user.created" }
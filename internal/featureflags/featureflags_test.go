@@ -0,0 +1,112 @@
+package featureflags
+
+import (
+	"fmt"
+	"testing"
+
+	"bitriver-live/internal/models"
+)
+
+func TestRaisingRolloutKeepsEarlierUsers(t *testing.T) {
+	flag := models.FeatureFlag{Key: "new-player", Enabled: true}
+	subjects := make([]string, 2000)
+	for i := range subjects {
+		subjects[i] = fmt.Sprintf("user-%d", i)
+	}
+
+	previous := map[string]bool{}
+	for _, percent := range []int{0, 5, 10, 25, 50, 75, 100} {
+		flag.RolloutPercent = percent
+		current := map[string]bool{}
+		for _, subject := range subjects {
+			if Enabled(flag, subject, subject) {
+				current[subject] = true
+			}
+		}
+		for subject := range previous {
+			if !current[subject] {
+				t.Fatalf("raising the rollout to %d%% dropped %s", percent, subject)
+			}
+		}
+		// A fair hash puts roughly the rollout percentage of users in.
+		share := len(current) * 100 / len(subjects)
+		if share < percent-5 || share > percent+5 {
+			t.Fatalf("expected about %d%% of users at a %d%% rollout, got %d%%", percent, percent, share)
+		}
+		previous = current
+	}
+}
+
+func TestBucketIsStable(t *testing.T) {
+	first := Bucket("new-player", "user-1")
+	for i := 0; i < 10; i++ {
+		if bucket := Bucket("new-player", "user-1"); bucket != first {
+			t.Fatalf("expected bucket %d every time, got %d", first, bucket)
+		}
+	}
+	if first < 0 || first >= Buckets {
+		t.Fatalf("expected a bucket in [0, %d), got %d", Buckets, first)
+	}
+}
+
+func TestBucketsDifferBetweenFlags(t *testing.T) {
+	same := 0
+	for i := 0; i < 100; i++ {
+		subject := fmt.Sprintf("user-%d", i)
+		if Bucket("new-player", subject) == Bucket("directory-ranking", subject) {
+			same++
+		}
+	}
+	if same > 5 {
+		t.Fatalf("expected flags to sample different users, %d of 100 shared a bucket", same)
+	}
+}
+
+func TestListsTakePrecedenceOverRollout(t *testing.T) {
+	flag := models.FeatureFlag{
+		Key:          "directory-ranking",
+		Enabled:      true,
+		AllowUserIDs: []string{"allowed", "both"},
+		DenyUserIDs:  []string{"denied", "both"},
+	}
+	if !Enabled(flag, "allowed", "allowed") {
+		t.Fatal("expected an allowed user to get a 0% rollout")
+	}
+	if Enabled(flag, "both", "both") {
+		t.Fatal("expected the deny list to win over the allow list")
+	}
+	flag.RolloutPercent = 100
+	if Enabled(flag, "denied", "denied") {
+		t.Fatal("expected a denied user to be left out of a full rollout")
+	}
+	if !Enabled(flag, "", "anonymous") {
+		t.Fatal("expected anonymous callers in a full rollout")
+	}
+	flag.Enabled = false
+	if Enabled(flag, "allowed", "allowed") {
+		t.Fatal("expected a disabled flag to be off even for allowed users")
+	}
+}
+
+func TestAnonymousCallersBucketBySubject(t *testing.T) {
+	flag := models.FeatureFlag{Key: "new-player", Enabled: true, RolloutPercent: 50}
+	if Enabled(flag, "", "") {
+		t.Fatal("expected a caller without a subject to be left out of a partial rollout")
+	}
+	in, out := 0, 0
+	for i := 0; i < 200; i++ {
+		subject := fmt.Sprintf("anon-%d", i)
+		if Enabled(flag, "", subject) {
+			in++
+		} else {
+			out++
+		}
+	}
+	if in == 0 || out == 0 {
+		t.Fatalf("expected anonymous callers split by their subject, got %d in and %d out", in, out)
+	}
+	values := Evaluate([]models.FeatureFlag{flag, {Key: "off"}}, "", "anon-1")
+	if len(values) != 2 || values["off"] || values["new-player"] != Enabled(flag, "", "anon-1") {
+		t.Fatalf("expected Evaluate to report each flag, got %v", values)
+	}
+}
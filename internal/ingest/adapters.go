@@ -4,12 +4,17 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
+	"net/url"
+	"sort"
 	"strings"
 	"time"
+
+	"bitriver-live/internal/models"
 )
 
 // Default values used when callers do not provide explicit settings.
@@ -17,6 +22,14 @@ const (
 	defaultHTTPTimeout  = 10 * time.Second
 	defaultMaxAttempts  = 3
 	defaultRetryBackoff = 500 * time.Millisecond
+	// defaultBootStepTimeout bounds each BootStream step, retries included.
+	defaultBootStepTimeout = 10 * time.Second
+	// defaultBootCleanupReserve is held back from a boot's deadline for
+	// rolling back what it created.
+	defaultBootCleanupReserve = 2 * time.Second
+
+	// maxFrameBytes caps still frames fetched from the transcoder.
+	maxFrameBytes = 8 << 20
 )
 
 type adapterConfig struct {
@@ -66,8 +79,8 @@ type channelAdapter interface {
 type applicationAdapter interface {
 	// CreateApplication provisions a new application for the given channelID
 	// and renditions. It returns the origin URL (used by the transcoder) and
-	// the playback URL (used by viewers).
-	CreateApplication(ctx context.Context, channelID string, renditions []string) (originURL, playbackURL string, err error)
+	// the playback endpoints (used by viewers).
+	CreateApplication(ctx context.Context, channelID string, renditions []string) (applicationResult, error)
 
 	// DeleteApplication removes the application associated with channelID.
 	DeleteApplication(ctx context.Context, channelID string) error
@@ -79,15 +92,38 @@ type transcoderAdapter interface {
 	// StartJobs starts one or more live transcoding jobs for the given
 	// channelID and sessionID, pulling from originURL using the provided
 	// rendition ladder. It returns job IDs and the effective renditions used.
-	StartJobs(ctx context.Context, channelID, sessionID, originURL string, ladder []Rendition) ([]string, []Rendition, error)
+	StartJobs(ctx context.Context, channelID, sessionID, originURL string, ladder []Rendition, limits models.TranscodeLimits) ([]string, []Rendition, error)
 
-	// StopJob stops a specific transcoding job by its jobID.
-	StopJob(ctx context.Context, jobID string) error
+	// StopJob stops a specific transcoding job by its jobID and returns the
+	// size of the output it left behind, or zero when the transcoder does
+	// not report it.
+	StopJob(ctx context.Context, jobID string) (int64, error)
 
 	// StartUpload starts a VOD transcoding/upload job for a previously
 	// uploaded source, identified by UploadID. It returns a job result that
 	// includes the playback URL and effective renditions.
 	StartUpload(ctx context.Context, req uploadJobRequest) (uploadJobResult, error)
+
+	// UploadStatus reports whether an upload job has finished and the size
+	// of its output.
+	UploadStatus(ctx context.Context, jobID string) (UploadOutput, error)
+
+	// FetchFrame downloads the latest still frame captured from a live job.
+	FetchFrame(ctx context.Context, jobID string) (Frame, error)
+
+	// CreateClip cuts the last durationSeconds of a live job's buffered
+	// output into a clip.
+	CreateClip(ctx context.Context, jobID string, durationSeconds int) (LiveClip, error)
+
+	// StartRestreams pushes originURL to each target, replacing any
+	// restreams already running for channelID.
+	StartRestreams(ctx context.Context, channelID, sessionID, originURL string, targets []RestreamTarget) error
+
+	// StopRestreams stops every restream of channelID.
+	StopRestreams(ctx context.Context, channelID string) error
+
+	// RestreamStatus reports the state of each restream of channelID.
+	RestreamStatus(ctx context.Context, channelID string) ([]RestreamStatus, error)
 }
 
 // httpChannelAdapter is an HTTP implementation of channelAdapter that
@@ -146,19 +182,32 @@ type omeApplicationRequest struct {
 }
 
 // omeApplicationResponse is the JSON response from the OME API when an
-// application is created.
+// application is created. Older control planes only return playbackUrl;
+// newer ones also list every protocol the application is served over.
 type omeApplicationResponse struct {
-	OriginURL   string `json:"originUrl"`
-	PlaybackURL string `json:"playbackUrl"`
+	OriginURL         string                    `json:"originUrl"`
+	PlaybackURL       string                    `json:"playbackUrl"`
+	PlaybackEndpoints []models.PlaybackEndpoint `json:"playbackEndpoints"`
+}
+
+// applicationResult is what CreateApplication returns: the origin URL the
+// transcoder pulls from and the endpoints viewers play from.
+type applicationResult struct {
+	OriginURL string
+	// PlaybackURL is the preferred HLS-family endpoint, kept for players
+	// that only understand a single URL.
+	PlaybackURL       string
+	PlaybackEndpoints []models.PlaybackEndpoint
 }
 
 // ffmpegJobRequest is the JSON payload sent to the transcoder service when
 // starting live jobs.
 type ffmpegJobRequest struct {
-	ChannelID  string      `json:"channelId"`
-	SessionID  string      `json:"sessionId"`
-	OriginURL  string      `json:"originUrl"`
-	Renditions []Rendition `json:"renditions"`
+	ChannelID  string                  `json:"channelId"`
+	SessionID  string                  `json:"sessionId"`
+	OriginURL  string                  `json:"originUrl"`
+	Renditions []Rendition             `json:"renditions"`
+	Limits     *models.TranscodeLimits `json:"limits,omitempty"`
 }
 
 // ffmpegJobResponse is the JSON response from the transcoder service when
@@ -172,25 +221,48 @@ type ffmpegJobResponse struct {
 	Renditions []Rendition `json:"renditions"`
 }
 
+// ffmpegJobStopResponse is the JSON response from the transcoder service
+// when a live job is stopped. Older backends answer 204 without a body.
+type ffmpegJobStopResponse struct {
+	JobID       string `json:"jobId"`
+	OutputBytes int64  `json:"outputBytes"`
+}
+
+// ffmpegRestreamRequest is the JSON payload sent to the transcoder service
+// when starting restreams for a live channel.
+type ffmpegRestreamRequest struct {
+	ChannelID string           `json:"channelId"`
+	SessionID string           `json:"sessionId"`
+	OriginURL string           `json:"originUrl"`
+	Targets   []RestreamTarget `json:"targets"`
+}
+
+// ffmpegRestreamResponse lists the restreams of a channel.
+type ffmpegRestreamResponse struct {
+	Restreams []RestreamStatus `json:"restreams"`
+}
+
 // uploadJobRequest represents a high-level request to start a VOD upload job.
 // This type is internal to the ingest package and is converted to a JSON
 // request for the transcoder service.
 type uploadJobRequest struct {
-	ChannelID  string
-	UploadID   string
-	SourceURL  string
-	Filename   string
-	Renditions []Rendition
+	ChannelID    string
+	UploadID     string
+	SourceURL    string
+	Filename     string
+	ExpectedHash string
+	Renditions   []Rendition
 }
 
 // ffmpegUploadRequest is the JSON payload sent to the transcoder service
 // when starting a VOD upload/transcode job.
 type ffmpegUploadRequest struct {
-	ChannelID  string      `json:"channelId"`
-	UploadID   string      `json:"uploadId"`
-	SourceURL  string      `json:"sourceUrl"`
-	Filename   string      `json:"filename,omitempty"`
-	Renditions []Rendition `json:"renditions,omitempty"`
+	ChannelID    string      `json:"channelId"`
+	UploadID     string      `json:"uploadId"`
+	SourceURL    string      `json:"sourceUrl"`
+	Filename     string      `json:"filename,omitempty"`
+	ExpectedHash string      `json:"expectedHash,omitempty"`
+	Renditions   []Rendition `json:"renditions,omitempty"`
 }
 
 // ffmpegUploadResponse is the JSON response from the transcoder service
@@ -199,14 +271,47 @@ type ffmpegUploadResponse struct {
 	JobID       string      `json:"jobId"`
 	PlaybackURL string      `json:"playbackUrl"`
 	Renditions  []Rendition `json:"renditions"`
+	Media       *MediaInfo  `json:"media,omitempty"`
+	// ThumbnailURL is a poster frame the transcoder grabbed from the
+	// source, if it generates one.
+	ThumbnailURL string `json:"thumbnailUrl,omitempty"`
+}
+
+// ffmpegUploadStatusResponse is the JSON response from the transcoder
+// service describing an upload job.
+type ffmpegUploadStatusResponse struct {
+	JobID       string `json:"jobId"`
+	Status      string `json:"status"`
+	OutputBytes int64  `json:"outputBytes"`
 }
 
 // uploadJobResult is a high-level result of starting a VOD upload job, used
 // internally by the ingest package.
 type uploadJobResult struct {
-	JobID       string
-	PlaybackURL string
-	Renditions  []Rendition
+	JobID        string
+	PlaybackURL  string
+	Renditions   []Rendition
+	Media        *MediaInfo
+	ThumbnailURL string
+}
+
+// httpStatusError captures a non-2xx response from an upstream service so
+// callers can inspect the status code and body. Its message matches the
+// "<status>: <body>" format used in logs.
+type httpStatusError struct {
+	StatusCode int
+	Status     string
+	Body       []byte
+}
+
+func (e *httpStatusError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Status, strings.TrimSpace(string(e.Body)))
+}
+
+// isNotFound reports whether err is a 404 from an upstream service.
+func isNotFound(err error) bool {
+	var statusErr *httpStatusError
+	return errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound
 }
 
 // newHTTPChannelAdapter constructs an HTTP-based channelAdapter.
@@ -288,7 +393,7 @@ func (a *httpChannelAdapter) DeleteChannel(ctx context.Context, channelID string
 //
 // The renditions slice is defensively copied to avoid accidental mutation by
 // callers after the request is initiated.
-func (a *httpApplicationAdapter) CreateApplication(ctx context.Context, channelID string, renditions []string) (string, string, error) {
+func (a *httpApplicationAdapter) CreateApplication(ctx context.Context, channelID string, renditions []string) (applicationResult, error) {
 	payload := omeApplicationRequest{
 		ChannelID:  channelID,
 		Renditions: append([]string{}, renditions...),
@@ -297,9 +402,89 @@ func (a *httpApplicationAdapter) CreateApplication(ctx context.Context, channelI
 	if err := postJSON(ctx, a.client, fmt.Sprintf("%s/v1/applications", a.baseURL), payload, &response, func(req *http.Request) {
 		req.SetBasicAuth(a.username, a.password)
 	}, a.logger, a.maxAttempts, a.retryInterval); err != nil {
-		return "", "", err
+		return applicationResult{}, err
+	}
+	endpoints := normalizePlaybackEndpoints(response.PlaybackEndpoints, response.PlaybackURL)
+	return applicationResult{
+		OriginURL:         response.OriginURL,
+		PlaybackURL:       legacyPlaybackURL(endpoints, response.PlaybackURL),
+		PlaybackEndpoints: endpoints,
+	}, nil
+}
+
+// defaultPlaybackPriority orders endpoints the origin did not rank: WebRTC
+// first for the lowest latency, then LL-HLS, then plain HLS.
+var defaultPlaybackPriority = map[string]int{
+	models.PlaybackProtocolWebRTC: 1,
+	models.PlaybackProtocolLLHLS:  2,
+	models.PlaybackProtocolHLS:    3,
+}
+
+// normalizePlaybackEndpoints cleans the endpoints an application response
+// lists: protocols are lower-cased, entries with an unknown protocol or no
+// URL are dropped, missing priorities take the protocol default, and the
+// result is sorted most preferred first. A response without endpoints is
+// described by its single playbackUrl, so sessions always carry at least
+// one endpoint when the origin returned a URL.
+func normalizePlaybackEndpoints(raw []models.PlaybackEndpoint, playbackURL string) []models.PlaybackEndpoint {
+	endpoints := make([]models.PlaybackEndpoint, 0, len(raw))
+	seen := make(map[string]struct{}, len(raw))
+	for _, endpoint := range raw {
+		protocol := strings.ToLower(strings.TrimSpace(endpoint.Protocol))
+		if protocol == "ll-hls" {
+			protocol = models.PlaybackProtocolLLHLS
+		}
+		defaultPriority, known := defaultPlaybackPriority[protocol]
+		target := strings.TrimSpace(endpoint.URL)
+		if !known || target == "" {
+			continue
+		}
+		if _, dup := seen[protocol+" "+target]; dup {
+			continue
+		}
+		seen[protocol+" "+target] = struct{}{}
+		priority := endpoint.Priority
+		if priority <= 0 {
+			priority = defaultPriority
+		}
+		endpoints = append(endpoints, models.PlaybackEndpoint{Protocol: protocol, URL: target, Priority: priority})
 	}
-	return response.OriginURL, response.PlaybackURL, nil
+	if len(endpoints) == 0 {
+		target := strings.TrimSpace(playbackURL)
+		if target == "" {
+			return nil
+		}
+		protocol := models.PlaybackProtocolHLS
+		if models.LatencyModeForPlaybackURL(target) == models.LatencyModeUltraLow {
+			protocol = models.PlaybackProtocolWebRTC
+		}
+		return []models.PlaybackEndpoint{{Protocol: protocol, URL: target, Priority: defaultPlaybackPriority[protocol]}}
+	}
+	sort.SliceStable(endpoints, func(i, j int) bool {
+		if endpoints[i].Priority != endpoints[j].Priority {
+			return endpoints[i].Priority < endpoints[j].Priority
+		}
+		return defaultPlaybackPriority[endpoints[i].Protocol] < defaultPlaybackPriority[endpoints[j].Protocol]
+	})
+	return endpoints
+}
+
+// legacyPlaybackURL picks the URL for the single-URL playback field: the
+// most preferred HLS-family endpoint, or the response's own playbackUrl
+// when the origin serves no HLS.
+func legacyPlaybackURL(endpoints []models.PlaybackEndpoint, fallback string) string {
+	for _, endpoint := range endpoints {
+		if endpoint.IsHLS() {
+			return endpoint.URL
+		}
+	}
+	if fallback = strings.TrimSpace(fallback); fallback != "" {
+		return fallback
+	}
+	if len(endpoints) > 0 {
+		return endpoints[0].URL
+	}
+	return ""
 }
 
 // DeleteApplication removes the application associated with channelID from
@@ -311,21 +496,37 @@ func (a *httpApplicationAdapter) DeleteApplication(ctx context.Context, channelI
 }
 
 // StartJobs starts one or more live transcoding jobs for the given channel,
-// session, and origin URL using the provided rendition ladder.
+// session, and origin URL using the provided rendition ladder. Non-zero
+// limits are sent along so the transcoder can clamp the ladder; a ladder it
+// cannot fit is reported as *TranscodeLimitError, and a transcoder whose
+// disk is full as ErrTranscoderOutOfStorage.
 //
 // The returned jobIDs slice may contain IDs from both JobID and JobIDs
 // response fields to maintain backward compatibility with older backends.
-func (a *httpTranscoderAdapter) StartJobs(ctx context.Context, channelID, sessionID, originURL string, ladder []Rendition) ([]string, []Rendition, error) {
+func (a *httpTranscoderAdapter) StartJobs(ctx context.Context, channelID, sessionID, originURL string, ladder []Rendition, limits models.TranscodeLimits) ([]string, []Rendition, error) {
 	payload := ffmpegJobRequest{
 		ChannelID:  channelID,
 		SessionID:  sessionID,
 		OriginURL:  originURL,
 		Renditions: CloneRenditions(ladder),
 	}
+	if !limits.IsZero() {
+		payload.Limits = &limits
+	}
 	var response ffmpegJobResponse
 	if err := postJSON(ctx, a.client, fmt.Sprintf("%s/v1/jobs", a.baseURL), payload, &response, func(req *http.Request) {
 		setBearer(req, a.token)
 	}, a.logger, a.maxAttempts, a.retryInterval); err != nil {
+		var statusErr *httpStatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusInsufficientStorage {
+			return nil, nil, fmt.Errorf("%w: %w", ErrTranscoderOutOfStorage, err)
+		}
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusUnprocessableEntity {
+			limitErr := &TranscodeLimitError{}
+			if decodeErr := json.Unmarshal(statusErr.Body, limitErr); decodeErr == nil && limitErr.Reason != "" {
+				return nil, nil, limitErr
+			}
+		}
 		return nil, nil, err
 	}
 
@@ -337,11 +538,20 @@ func (a *httpTranscoderAdapter) StartJobs(ctx context.Context, channelID, sessio
 	return jobIDs, renditions, nil
 }
 
-// StopJob stops a live transcoding job with the specified jobID.
-func (a *httpTranscoderAdapter) StopJob(ctx context.Context, jobID string) error {
-	return deleteRequest(ctx, a.client, fmt.Sprintf("%s/v1/jobs/%s", a.baseURL, jobID), func(req *http.Request) {
+// StopJob stops a live transcoding job with the specified jobID and returns
+// the size of its output. Transcoders that answer 204 report zero.
+func (a *httpTranscoderAdapter) StopJob(ctx context.Context, jobID string) (int64, error) {
+	client := a.client
+	if client == nil {
+		client = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+	var response ffmpegJobStopResponse
+	if err := doWithRetry(ctx, client, http.MethodDelete, fmt.Sprintf("%s/v1/jobs/%s", a.baseURL, jobID), nil, func(req *http.Request) {
 		setBearer(req, a.token)
-	}, a.logger, a.maxAttempts, a.retryInterval)
+	}, &response, a.logger, a.maxAttempts, a.retryInterval); err != nil {
+		return 0, err
+	}
+	return response.OutputBytes, nil
 }
 
 // StartUpload starts a VOD transcoding/upload job for the given upload
@@ -351,25 +561,188 @@ func (a *httpTranscoderAdapter) StopJob(ctx context.Context, jobID string) error
 // Renditions are defensively copied to avoid aliasing.
 func (a *httpTranscoderAdapter) StartUpload(ctx context.Context, req uploadJobRequest) (uploadJobResult, error) {
 	payload := ffmpegUploadRequest{
-		ChannelID:  req.ChannelID,
-		UploadID:   req.UploadID,
-		SourceURL:  req.SourceURL,
-		Filename:   req.Filename,
-		Renditions: CloneRenditions(req.Renditions),
+		ChannelID:    req.ChannelID,
+		UploadID:     req.UploadID,
+		SourceURL:    req.SourceURL,
+		Filename:     req.Filename,
+		ExpectedHash: req.ExpectedHash,
+		Renditions:   CloneRenditions(req.Renditions),
 	}
 	var response ffmpegUploadResponse
 	if err := postJSON(ctx, a.client, fmt.Sprintf("%s/v1/uploads", a.baseURL), payload, &response, func(httpReq *http.Request) {
 		setBearer(httpReq, a.token)
 	}, a.logger, a.maxAttempts, a.retryInterval); err != nil {
+		var statusErr *httpStatusError
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusInsufficientStorage {
+			return uploadJobResult{}, fmt.Errorf("%w: %w", ErrTranscoderOutOfStorage, err)
+		}
+		if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusUnprocessableEntity {
+			rejection := &UploadRejectedError{}
+			if decodeErr := json.Unmarshal(statusErr.Body, rejection); decodeErr != nil || rejection.Reason == "" {
+				rejection.Reason = "rejected"
+				rejection.Message = strings.TrimSpace(string(statusErr.Body))
+			}
+			return uploadJobResult{}, rejection
+		}
 		return uploadJobResult{}, err
 	}
 	return uploadJobResult{
-		JobID:       response.JobID,
-		PlaybackURL: response.PlaybackURL,
-		Renditions:  CloneRenditions(response.Renditions),
+		JobID:        response.JobID,
+		PlaybackURL:  response.PlaybackURL,
+		Renditions:   CloneRenditions(response.Renditions),
+		Media:        response.Media,
+		ThumbnailURL: response.ThumbnailURL,
+	}, nil
+}
+
+// UploadStatus fetches the state of an upload job. Status is informational,
+// so the request is attempted once; a 404, including from transcoders that
+// predate the endpoint, maps to ErrUploadOutputUnavailable.
+func (a *httpTranscoderAdapter) UploadStatus(ctx context.Context, jobID string) (UploadOutput, error) {
+	client := a.client
+	if client == nil {
+		client = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+	var response ffmpegUploadStatusResponse
+	err := doWithRetry(ctx, client, http.MethodGet, fmt.Sprintf("%s/v1/uploads/%s", a.baseURL, url.PathEscape(jobID)), nil, func(req *http.Request) {
+		setBearer(req, a.token)
+	}, &response, a.logger, 1, 0)
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		return UploadOutput{}, ErrUploadOutputUnavailable
+	}
+	if err != nil {
+		return UploadOutput{}, err
+	}
+	switch response.Status {
+	case UploadOutputRunning, UploadOutputCompleted, UploadOutputFailed:
+	default:
+		return UploadOutput{}, fmt.Errorf("transcoder reported unknown upload status %q", response.Status)
+	}
+	return UploadOutput{Status: response.Status, Bytes: response.OutputBytes}, nil
+}
+
+// FetchFrame downloads the latest still frame the transcoder captured for
+// jobID. Frames are best-effort, so the request is attempted once and a 404
+// maps to ErrFrameUnavailable.
+func (a *httpTranscoderAdapter) FetchFrame(ctx context.Context, jobID string) (Frame, error) {
+	client := a.client
+	if client == nil {
+		client = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, fmt.Sprintf("%s/v1/jobs/%s/frame", a.baseURL, jobID), nil)
+	if err != nil {
+		return Frame{}, fmt.Errorf("build request: %w", err)
+	}
+	setBearer(req, a.token)
+	resp, err := client.Do(req)
+	if err != nil {
+		return Frame{}, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode == http.StatusNotFound {
+		return Frame{}, ErrFrameUnavailable
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		return Frame{}, &httpStatusError{StatusCode: resp.StatusCode, Status: resp.Status, Body: body}
+	}
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxFrameBytes+1))
+	if err != nil {
+		return Frame{}, fmt.Errorf("read frame: %w", err)
+	}
+	if len(data) > maxFrameBytes {
+		return Frame{}, fmt.Errorf("frame exceeds %d bytes", maxFrameBytes)
+	}
+	if len(data) == 0 {
+		return Frame{}, ErrFrameUnavailable
+	}
+	contentType := resp.Header.Get("Content-Type")
+	if contentType == "" {
+		contentType = "image/jpeg"
+	}
+	return Frame{Data: data, ContentType: contentType}, nil
+}
+
+// CreateClip asks the transcoder to cut the newest durationSeconds of jobID
+// into an MP4. The request is attempted once so a slow transcoder cannot
+// produce duplicate clips; a 404 or 409 maps to ErrClipUnavailable.
+func (a *httpTranscoderAdapter) CreateClip(ctx context.Context, jobID string, durationSeconds int) (LiveClip, error) {
+	var response struct {
+		ClipID          string `json:"clipId"`
+		PlaybackURL     string `json:"playbackUrl"`
+		DurationSeconds int    `json:"durationSeconds"`
+	}
+	payload := map[string]int{"duration": durationSeconds}
+	if err := postJSON(ctx, a.client, fmt.Sprintf("%s/v1/jobs/%s/clip", a.baseURL, jobID), payload, &response, func(req *http.Request) {
+		setBearer(req, a.token)
+	}, a.logger, 1, 0); err != nil {
+		var statusErr *httpStatusError
+		if errors.As(err, &statusErr) && (statusErr.StatusCode == http.StatusNotFound || statusErr.StatusCode == http.StatusConflict) {
+			return LiveClip{}, ErrClipUnavailable
+		}
+		return LiveClip{}, err
+	}
+	if response.PlaybackURL == "" {
+		return LiveClip{}, fmt.Errorf("transcoder returned no clip playback URL")
+	}
+	return LiveClip{
+		ID:              response.ClipID,
+		PlaybackURL:     response.PlaybackURL,
+		DurationSeconds: response.DurationSeconds,
 	}, nil
 }
 
+// StartRestreams asks the transcoder to push originURL to each target.
+func (a *httpTranscoderAdapter) StartRestreams(ctx context.Context, channelID, sessionID, originURL string, targets []RestreamTarget) error {
+	payload := ffmpegRestreamRequest{
+		ChannelID: channelID,
+		SessionID: sessionID,
+		OriginURL: originURL,
+		Targets:   append([]RestreamTarget{}, targets...),
+	}
+	return postJSON(ctx, a.client, fmt.Sprintf("%s/v1/restreams", a.baseURL), payload, nil, func(req *http.Request) {
+		setBearer(req, a.token)
+	}, a.logger, a.maxAttempts, a.retryInterval)
+}
+
+// StopRestreams stops the restreams of channelID. Transcoders that predate
+// restreams answer 404, which is treated as nothing to stop.
+func (a *httpTranscoderAdapter) StopRestreams(ctx context.Context, channelID string) error {
+	err := deleteRequest(ctx, a.client, fmt.Sprintf("%s/v1/restreams/%s", a.baseURL, url.PathEscape(channelID)), func(req *http.Request) {
+		setBearer(req, a.token)
+	}, a.logger, a.maxAttempts, a.retryInterval)
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		return nil
+	}
+	return err
+}
+
+// RestreamStatus fetches the state of each restream of channelID. Status is
+// informational, so the request is attempted once.
+func (a *httpTranscoderAdapter) RestreamStatus(ctx context.Context, channelID string) ([]RestreamStatus, error) {
+	client := a.client
+	if client == nil {
+		client = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+	var response ffmpegRestreamResponse
+	err := doWithRetry(ctx, client, http.MethodGet, fmt.Sprintf("%s/v1/restreams/%s", a.baseURL, url.PathEscape(channelID)), nil, func(req *http.Request) {
+		setBearer(req, a.token)
+	}, &response, a.logger, 1, 0)
+	var statusErr *httpStatusError
+	if errors.As(err, &statusErr) && statusErr.StatusCode == http.StatusNotFound {
+		return []RestreamStatus{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if response.Restreams == nil {
+		return []RestreamStatus{}, nil
+	}
+	return response.Restreams, nil
+}
+
 // postJSON issues an HTTP POST with a JSON payload and decodes the JSON
 // response into dest (if non-nil). It uses retry semantics defined by
 // doWithRetry. If client is nil, a temporary client with a default timeout
@@ -476,8 +849,8 @@ func doWithRetry(
 				statusCode := resp.StatusCode
 
 				if statusCode >= 200 && statusCode < 300 {
-					// Success.
-					if dest == nil {
+					// Success. A 204 carries no body to decode.
+					if dest == nil || statusCode == http.StatusNoContent {
 						lastErr = nil
 						return
 					}
@@ -492,7 +865,7 @@ func doWithRetry(
 
 				// Read response body for diagnostics.
 				data, _ := io.ReadAll(resp.Body)
-				errMsg := fmt.Errorf("%s: %s", resp.Status, strings.TrimSpace(string(data)))
+				errMsg := &httpStatusError{StatusCode: statusCode, Status: resp.Status, Body: data}
 
 				// Determine if this status code is retryable.
 				if isRetryableStatus(statusCode) {
@@ -543,12 +916,16 @@ func doWithRetry(
 // isRetryableStatus reports whether an HTTP status code should be treated
 // as transient and therefore retried.
 //
-// We currently consider 5xx and 429 as retryable. All other 4xx responses
-// are treated as permanent failures.
+// We currently consider 5xx and 429 as retryable, except 507: a transcoder
+// that is out of disk stays that way until someone frees space. All other
+// 4xx responses are treated as permanent failures.
 func isRetryableStatus(statusCode int) bool {
 	if statusCode == http.StatusTooManyRequests {
 		return true
 	}
+	if statusCode == http.StatusInsufficientStorage {
+		return false
+	}
 	if statusCode >= 500 && statusCode <= 599 {
 		return true
 	}
@@ -3,11 +3,15 @@ package ingest
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"log/slog"
 	"net/http"
 	"net/http/httptest"
+	"sync/atomic"
 	"testing"
 	"time"
+
+	"bitriver-live/internal/models"
 )
 
 func TestHTTPAdapterConstructorsNormalizeDefaults(t *testing.T) {
@@ -252,12 +256,12 @@ func TestHTTPApplicationAdapterLifecycle(t *testing.T) {
 	defer server.Close()
 
 	adapter := newHTTPApplicationAdapter(server.URL, "admin", "password", server.Client(), nil, 3, time.Nanosecond)
-	origin, playback, err := adapter.CreateApplication(context.Background(), "channel-123", []string{"1080p"})
+	app, err := adapter.CreateApplication(context.Background(), "channel-123", []string{"1080p"})
 	if err != nil {
 		t.Fatalf("CreateApplication: %v", err)
 	}
-	if origin != "http://origin" || playback != "https://playback" {
-		t.Fatalf("unexpected playback URLs: %q %q", origin, playback)
+	if app.OriginURL != "http://origin" || app.PlaybackURL != "https://playback" {
+		t.Fatalf("unexpected playback URLs: %q %q", app.OriginURL, app.PlaybackURL)
 	}
 	if !created {
 		t.Fatal("expected application creation to be invoked")
@@ -292,6 +296,9 @@ func TestHTTPTranscoderAdapterStartStop(t *testing.T) {
 			if payload.ChannelID != "channel-123" || payload.SessionID != "session-abc" {
 				t.Fatalf("unexpected payload: %+v", payload)
 			}
+			if payload.Limits != nil {
+				t.Fatalf("expected no limits without caps, got %+v", payload.Limits)
+			}
 			if err := json.NewEncoder(w).Encode(ffmpegJobResponse{
 				JobID:  "job-primary",
 				JobIDs: []string{"job-a", "job-b"},
@@ -317,7 +324,7 @@ func TestHTTPTranscoderAdapterStartStop(t *testing.T) {
 
 	adapter := newHTTPTranscoderAdapter(server.URL, "job-token", server.Client(), nil, 3, time.Nanosecond)
 	ladder := []Rendition{{Name: "1080p", Bitrate: 6000}}
-	jobIDs, renditions, err := adapter.StartJobs(context.Background(), "channel-123", "session-abc", "http://origin", ladder)
+	jobIDs, renditions, err := adapter.StartJobs(context.Background(), "channel-123", "session-abc", "http://origin", ladder, models.TranscodeLimits{})
 	if err != nil {
 		t.Fatalf("StartJobs: %v", err)
 	}
@@ -335,14 +342,242 @@ func TestHTTPTranscoderAdapterStartStop(t *testing.T) {
 		t.Fatalf("expected input ladder to remain unchanged, got manifest %q", ladder[0].ManifestURL)
 	}
 
-	if err := adapter.StopJob(context.Background(), "job-a"); err != nil {
-		t.Fatalf("StopJob: %v", err)
+	if size, err := adapter.StopJob(context.Background(), "job-a"); err != nil || size != 0 {
+		t.Fatalf("expected a 204 stop to report no output size, got %d, %v", size, err)
 	}
 	if !stopped {
 		t.Fatal("expected stop endpoint to be invoked")
 	}
 }
 
+// TestHTTPTranscoderAdapterReportsOutputSizes verifies that job stops and
+// upload status lookups relay the output sizes the transcoder reports.
+func TestHTTPTranscoderAdapterReportsOutputSizes(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch {
+		case r.Method == http.MethodDelete && r.URL.Path == "/v1/jobs/job-a":
+			_ = json.NewEncoder(w).Encode(ffmpegJobStopResponse{JobID: "job-a", OutputBytes: 4096})
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/uploads/upload-a":
+			_ = json.NewEncoder(w).Encode(ffmpegUploadStatusResponse{JobID: "upload-a", Status: "completed", OutputBytes: 8192})
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/uploads/upload-b":
+			_ = json.NewEncoder(w).Encode(ffmpegUploadStatusResponse{JobID: "upload-b", Status: "running"})
+		default:
+			http.NotFound(w, r)
+		}
+	}))
+	defer server.Close()
+
+	adapter := newHTTPTranscoderAdapter(server.URL, "job-token", server.Client(), nil, 1, 0)
+	if size, err := adapter.StopJob(context.Background(), "job-a"); err != nil || size != 4096 {
+		t.Fatalf("expected 4096 output bytes, got %d, %v", size, err)
+	}
+	if status, err := adapter.UploadStatus(context.Background(), "upload-a"); err != nil || status != (UploadOutput{Status: UploadOutputCompleted, Bytes: 8192}) {
+		t.Fatalf("unexpected completed upload status: %+v, %v", status, err)
+	}
+	if status, err := adapter.UploadStatus(context.Background(), "upload-b"); err != nil || status.Status != UploadOutputRunning {
+		t.Fatalf("unexpected running upload status: %+v, %v", status, err)
+	}
+	if _, err := adapter.UploadStatus(context.Background(), "upload-missing"); !errors.Is(err, ErrUploadOutputUnavailable) {
+		t.Fatalf("expected ErrUploadOutputUnavailable for an unknown job, got %v", err)
+	}
+}
+
+// TestHTTPTranscoderAdapterFetchFrame verifies that frames are downloaded with
+// the job token and that a missing frame maps to ErrFrameUnavailable.
+func TestHTTPTranscoderAdapterStartJobsLimitRejected(t *testing.T) {
+	var received *models.TranscodeLimits
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var payload ffmpegJobRequest
+		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+			t.Fatalf("decode request: %v", err)
+		}
+		received = payload.Limits
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_ = json.NewEncoder(w).Encode(TranscodeLimitError{Reason: LimitReasonBitrate, Message: "too much", Limit: 3000, Value: 6000})
+	}))
+	defer server.Close()
+
+	adapter := newHTTPTranscoderAdapter(server.URL, "job-token", server.Client(), nil, 3, time.Nanosecond)
+	limits := models.TranscodeLimits{MaxBitrate: 3000}
+	_, _, err := adapter.StartJobs(context.Background(), "channel-123", "session-abc", "http://origin", []Rendition{{Name: "1080p", Bitrate: 6000}}, limits)
+	var limitErr *TranscodeLimitError
+	if !errors.As(err, &limitErr) {
+		t.Fatalf("expected TranscodeLimitError, got %v", err)
+	}
+	if limitErr.Reason != LimitReasonBitrate || limitErr.Limit != 3000 || limitErr.Value != 6000 {
+		t.Fatalf("unexpected rejection: %+v", limitErr)
+	}
+	if received == nil || *received != limits {
+		t.Fatalf("expected limits %+v in request, got %+v", limits, received)
+	}
+}
+
+func TestHTTPTranscoderAdapterOutOfStorageFailsFast(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		http.Error(w, "transcoder out of storage: output volume has 10 bytes free", http.StatusInsufficientStorage)
+	}))
+	defer server.Close()
+
+	adapter := newHTTPTranscoderAdapter(server.URL, "job-token", server.Client(), nil, 3, time.Nanosecond)
+	_, _, err := adapter.StartJobs(context.Background(), "channel-123", "session-abc", "http://origin", []Rendition{{Name: "720p", Bitrate: 3000}}, models.TranscodeLimits{})
+	if !errors.Is(err, ErrTranscoderOutOfStorage) {
+		t.Fatalf("expected ErrTranscoderOutOfStorage from StartJobs, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected a single StartJobs attempt, got %d", calls)
+	}
+
+	calls = 0
+	_, err = adapter.StartUpload(context.Background(), uploadJobRequest{ChannelID: "channel-123", UploadID: "upload-abc", SourceURL: "https://cdn/video.mp4"})
+	if !errors.Is(err, ErrTranscoderOutOfStorage) {
+		t.Fatalf("expected ErrTranscoderOutOfStorage from StartUpload, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected a single StartUpload attempt, got %d", calls)
+	}
+}
+
+func TestHTTPTranscoderAdapterFetchFrame(t *testing.T) {
+	jpeg := []byte{0xff, 0xd8, 0xff, 0xd9}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer job-token" {
+			t.Fatalf("expected bearer token, got %q", got)
+		}
+		switch r.URL.Path {
+		case "/v1/jobs/job-a/frame":
+			w.Header().Set("Content-Type", "image/jpeg")
+			_, _ = w.Write(jpeg)
+		case "/v1/jobs/job-missing/frame":
+			http.NotFound(w, r)
+		default:
+			t.Fatalf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	adapter := newHTTPTranscoderAdapter(server.URL, "job-token", server.Client(), nil, 3, time.Nanosecond)
+	frame, err := adapter.FetchFrame(context.Background(), "job-a")
+	if err != nil {
+		t.Fatalf("FetchFrame: %v", err)
+	}
+	if frame.ContentType != "image/jpeg" || string(frame.Data) != string(jpeg) {
+		t.Fatalf("unexpected frame: %q %v", frame.ContentType, frame.Data)
+	}
+	if _, err := adapter.FetchFrame(context.Background(), "job-missing"); !errors.Is(err, ErrFrameUnavailable) {
+		t.Fatalf("expected ErrFrameUnavailable, got %v", err)
+	}
+}
+
+// TestHTTPTranscoderAdapterCreateClip verifies that clip requests carry the
+// duration, are never retried, and that a job without buffered output maps to
+// ErrClipUnavailable.
+func TestHTTPTranscoderAdapterCreateClip(t *testing.T) {
+	var attempts atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		attempts.Add(1)
+		if got := r.Header.Get("Authorization"); got != "Bearer job-token" {
+			t.Fatalf("expected bearer token, got %q", got)
+		}
+		switch r.URL.Path {
+		case "/v1/jobs/job-a/clip":
+			var payload struct {
+				Duration int `json:"duration"`
+			}
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				t.Fatalf("decode request: %v", err)
+			}
+			if payload.Duration != 30 {
+				t.Fatalf("expected a 30s clip request, got %d", payload.Duration)
+			}
+			w.WriteHeader(http.StatusCreated)
+			_ = json.NewEncoder(w).Encode(map[string]any{"clipId": "clip-1", "playbackUrl": "https://cdn/clips/clip-1.mp4", "durationSeconds": 28})
+		case "/v1/jobs/job-empty/clip":
+			http.Error(w, "no buffered segments", http.StatusConflict)
+		case "/v1/jobs/job-broken/clip":
+			http.Error(w, "failed", http.StatusInternalServerError)
+		default:
+			t.Fatalf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	adapter := newHTTPTranscoderAdapter(server.URL, "job-token", server.Client(), nil, 3, time.Nanosecond)
+	clip, err := adapter.CreateClip(context.Background(), "job-a", 30)
+	if err != nil {
+		t.Fatalf("CreateClip: %v", err)
+	}
+	if clip.ID != "clip-1" || clip.PlaybackURL != "https://cdn/clips/clip-1.mp4" || clip.DurationSeconds != 28 {
+		t.Fatalf("unexpected clip: %+v", clip)
+	}
+	if _, err := adapter.CreateClip(context.Background(), "job-empty", 30); !errors.Is(err, ErrClipUnavailable) {
+		t.Fatalf("expected ErrClipUnavailable, got %v", err)
+	}
+	attempts.Store(0)
+	if _, err := adapter.CreateClip(context.Background(), "job-broken", 30); err == nil {
+		t.Fatal("expected transcoder failure to be returned")
+	}
+	if got := attempts.Load(); got != 1 {
+		t.Fatalf("expected clip creation not to be retried, got %d attempts", got)
+	}
+}
+
+// TestHTTPTranscoderAdapterRestreams verifies the restream requests and that
+// transcoders without restream support are treated as having none.
+func TestHTTPTranscoderAdapterRestreams(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if got := r.Header.Get("Authorization"); got != "Bearer job-token" {
+			t.Fatalf("expected bearer token, got %q", got)
+		}
+		switch {
+		case r.Method == http.MethodPost && r.URL.Path == "/v1/restreams":
+			var payload ffmpegRestreamRequest
+			if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
+				t.Fatalf("decode request: %v", err)
+			}
+			if payload.ChannelID != "channel-1" || payload.OriginURL != "http://origin" || len(payload.Targets) != 1 || payload.Targets[0].StreamKey != "secret" {
+				t.Fatalf("unexpected restream request: %+v", payload)
+			}
+			w.WriteHeader(http.StatusCreated)
+			_, _ = w.Write([]byte(`{"restreams":[{"targetId":"target-1","state":"connected"}]}`))
+		case r.Method == http.MethodGet && r.URL.Path == "/v1/restreams/channel-1":
+			_, _ = w.Write([]byte(`{"restreams":[{"targetId":"target-1","state":"retrying","restarts":2,"lastError":"exit status 1"}]}`))
+		case r.Method == http.MethodDelete && r.URL.Path == "/v1/restreams/channel-1":
+			w.WriteHeader(http.StatusNoContent)
+		case r.URL.Path == "/v1/restreams/legacy":
+			http.NotFound(w, r)
+		default:
+			t.Fatalf("unexpected request: %s %s", r.Method, r.URL.Path)
+		}
+	}))
+	defer server.Close()
+
+	adapter := newHTTPTranscoderAdapter(server.URL, "job-token", server.Client(), nil, 3, time.Nanosecond)
+	targets := []RestreamTarget{{ID: "target-1", URL: "rtmp://live.example.com/app", StreamKey: "secret"}}
+	if err := adapter.StartRestreams(context.Background(), "channel-1", "session-1", "http://origin", targets); err != nil {
+		t.Fatalf("StartRestreams: %v", err)
+	}
+	statuses, err := adapter.RestreamStatus(context.Background(), "channel-1")
+	if err != nil {
+		t.Fatalf("RestreamStatus: %v", err)
+	}
+	want := RestreamStatus{TargetID: "target-1", State: RestreamRetrying, Restarts: 2, LastError: "exit status 1"}
+	if len(statuses) != 1 || statuses[0] != want {
+		t.Fatalf("expected %+v, got %+v", want, statuses)
+	}
+	if err := adapter.StopRestreams(context.Background(), "channel-1"); err != nil {
+		t.Fatalf("StopRestreams: %v", err)
+	}
+
+	if err := adapter.StopRestreams(context.Background(), "legacy"); err != nil {
+		t.Fatalf("expected a 404 to mean nothing to stop, got %v", err)
+	}
+	if statuses, err := adapter.RestreamStatus(context.Background(), "legacy"); err != nil || statuses == nil || len(statuses) != 0 {
+		t.Fatalf("expected no restreams from a legacy transcoder, got %v (err %v)", statuses, err)
+	}
+}
+
 // TestHTTPTranscoderAdapterStartUpload verifies that the transcoder adapter
 // correctly starts an upload/VOD job and returns the expected job result.
 func TestHTTPTranscoderAdapterStartUpload(t *testing.T) {
@@ -361,7 +596,7 @@ func TestHTTPTranscoderAdapterStartUpload(t *testing.T) {
 		if err := json.NewDecoder(r.Body).Decode(&payload); err != nil {
 			t.Fatalf("decode request: %v", err)
 		}
-		if payload.ChannelID != "channel-123" || payload.UploadID != "upload-abc" || payload.SourceURL != "https://cdn/source.mp4" {
+		if payload.ChannelID != "channel-123" || payload.UploadID != "upload-abc" || payload.SourceURL != "https://cdn/source.mp4" || payload.ExpectedHash != "abc123" {
 			t.Fatalf("unexpected payload: %+v", payload)
 		}
 		if err := json.NewEncoder(w).Encode(ffmpegUploadResponse{
@@ -380,10 +615,11 @@ func TestHTTPTranscoderAdapterStartUpload(t *testing.T) {
 
 	adapter := newHTTPTranscoderAdapter(server.URL, "job-token", server.Client(), nil, 3, time.Nanosecond)
 	result, err := adapter.StartUpload(context.Background(), uploadJobRequest{
-		ChannelID: "channel-123",
-		UploadID:  "upload-abc",
-		SourceURL: "https://cdn/source.mp4",
-		Filename:  "source.mp4",
+		ChannelID:    "channel-123",
+		UploadID:     "upload-abc",
+		SourceURL:    "https://cdn/source.mp4",
+		Filename:     "source.mp4",
+		ExpectedHash: "abc123",
 		Renditions: []Rendition{{
 			Name:    "720p",
 			Bitrate: 3000,
@@ -402,3 +638,33 @@ func TestHTTPTranscoderAdapterStartUpload(t *testing.T) {
 		t.Fatalf("unexpected renditions: %+v", result.Renditions)
 	}
 }
+
+// TestHTTPTranscoderAdapterStartUploadRejected verifies that a 422 validation
+// response is surfaced as an UploadRejectedError without retrying.
+func TestHTTPTranscoderAdapterStartUploadRejected(t *testing.T) {
+	var calls int
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls++
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusUnprocessableEntity)
+		_ = json.NewEncoder(w).Encode(map[string]string{"reason": "not_video", "message": "file is not a video"})
+	}))
+	defer server.Close()
+
+	adapter := newHTTPTranscoderAdapter(server.URL, "job-token", server.Client(), nil, 3, time.Nanosecond)
+	_, err := adapter.StartUpload(context.Background(), uploadJobRequest{
+		ChannelID: "channel-123",
+		UploadID:  "upload-abc",
+		SourceURL: "https://cdn/podcast.mp3",
+	})
+	var rejection *UploadRejectedError
+	if !errors.As(err, &rejection) {
+		t.Fatalf("expected UploadRejectedError, got %v", err)
+	}
+	if rejection.Reason != "not_video" || rejection.Message != "file is not a video" {
+		t.Fatalf("unexpected rejection: %+v", rejection)
+	}
+	if calls != 1 {
+		t.Fatalf("expected a single attempt, got %d", calls)
+	}
+}
@@ -9,18 +9,23 @@ import (
 	"strconv"
 	"strings"
 	"time"
+
+	"bitriver-live/internal/models"
 )
 
 // Config stores connectivity information for the ingest controller.
 type Config struct {
-	SRSBaseURL        string
-	SRSToken          string
-	OMEBaseURL        string
-	OMEUsername       string
-	OMEPassword       string
-	JobBaseURL        string
-	JobToken          string
-	LadderProfiles    []Rendition
+	SRSBaseURL     string
+	SRSToken       string
+	OMEBaseURL     string
+	OMEUsername    string
+	OMEPassword    string
+	JobBaseURL     string
+	JobToken       string
+	LadderProfiles []Rendition
+	// TranscodeLimits holds the platform-wide transcode caps. Channels may
+	// override individual fields through BootParams.TranscodeLimits.
+	TranscodeLimits   models.TranscodeLimits
 	HTTPClient        *http.Client
 	HealthEndpoint    string
 	HealthTimeout     time.Duration
@@ -28,24 +33,38 @@ type Config struct {
 	RetryInterval     time.Duration
 	HTTPMaxAttempts   int
 	HTTPRetryInterval time.Duration
+	// BootStepTimeout bounds each step of BootStream, such as creating the
+	// SRS channel, independently of the caller's overall deadline. Zero
+	// uses a 10s default.
+	BootStepTimeout time.Duration
+	// BootCleanupReserve is held back from the caller's deadline so a boot
+	// that runs out of time can still delete what it created. Zero uses a
+	// 2s default.
+	BootCleanupReserve time.Duration
+	// Transcoders lists the transcoder backends live and upload jobs are
+	// placed on. When empty, JobBaseURL and JobToken describe the only
+	// backend.
+	Transcoders []TranscoderBackend
 }
 
 // LoadConfigFromEnv initialises a Config from environment variables.
 func LoadConfigFromEnv() (Config, error) {
 	cfg := Config{
-		SRSBaseURL:        strings.TrimSpace(os.Getenv("BITRIVER_SRS_API")),
-		SRSToken:          strings.TrimSpace(os.Getenv("BITRIVER_SRS_TOKEN")),
-		OMEBaseURL:        strings.TrimSpace(os.Getenv("BITRIVER_OME_API")),
-		OMEUsername:       strings.TrimSpace(os.Getenv("BITRIVER_OME_USERNAME")),
-		OMEPassword:       strings.TrimSpace(os.Getenv("BITRIVER_OME_PASSWORD")),
-		JobBaseURL:        strings.TrimSpace(os.Getenv("BITRIVER_TRANSCODER_API")),
-		JobToken:          strings.TrimSpace(os.Getenv("BITRIVER_TRANSCODER_TOKEN")),
-		HealthEndpoint:    strings.TrimSpace(os.Getenv("BITRIVER_INGEST_HEALTH")),
-		HealthTimeout:     2 * time.Second,
-		MaxBootAttempts:   3,
-		RetryInterval:     500 * time.Millisecond,
-		HTTPMaxAttempts:   30,
-		HTTPRetryInterval: 2 * time.Second,
+		SRSBaseURL:         strings.TrimSpace(os.Getenv("BITRIVER_SRS_API")),
+		SRSToken:           strings.TrimSpace(os.Getenv("BITRIVER_SRS_TOKEN")),
+		OMEBaseURL:         strings.TrimSpace(os.Getenv("BITRIVER_OME_API")),
+		OMEUsername:        strings.TrimSpace(os.Getenv("BITRIVER_OME_USERNAME")),
+		OMEPassword:        strings.TrimSpace(os.Getenv("BITRIVER_OME_PASSWORD")),
+		JobBaseURL:         strings.TrimSpace(os.Getenv("BITRIVER_TRANSCODER_API")),
+		JobToken:           strings.TrimSpace(os.Getenv("BITRIVER_TRANSCODER_TOKEN")),
+		HealthEndpoint:     strings.TrimSpace(os.Getenv("BITRIVER_INGEST_HEALTH")),
+		HealthTimeout:      2 * time.Second,
+		MaxBootAttempts:    3,
+		RetryInterval:      500 * time.Millisecond,
+		HTTPMaxAttempts:    30,
+		HTTPRetryInterval:  2 * time.Second,
+		BootStepTimeout:    defaultBootStepTimeout,
+		BootCleanupReserve: defaultBootCleanupReserve,
 	}
 
 	if attempts := strings.TrimSpace(os.Getenv("BITRIVER_INGEST_MAX_BOOT_ATTEMPTS")); attempts != "" {
@@ -88,6 +107,26 @@ func LoadConfigFromEnv() (Config, error) {
 		}
 	}
 
+	if timeout := strings.TrimSpace(os.Getenv("BITRIVER_INGEST_STEP_TIMEOUT")); timeout != "" {
+		parsed, err := time.ParseDuration(timeout)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse BITRIVER_INGEST_STEP_TIMEOUT: %w", err)
+		}
+		if parsed > 0 {
+			cfg.BootStepTimeout = parsed
+		}
+	}
+
+	if reserve := strings.TrimSpace(os.Getenv("BITRIVER_INGEST_CLEANUP_RESERVE")); reserve != "" {
+		parsed, err := time.ParseDuration(reserve)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse BITRIVER_INGEST_CLEANUP_RESERVE: %w", err)
+		}
+		if parsed > 0 {
+			cfg.BootCleanupReserve = parsed
+		}
+	}
+
 	if timeout := strings.TrimSpace(os.Getenv("BITRIVER_INGEST_HEALTH_TIMEOUT")); timeout != "" {
 		parsed, err := time.ParseDuration(timeout)
 		if err != nil {
@@ -98,6 +137,14 @@ func LoadConfigFromEnv() (Config, error) {
 		}
 	}
 
+	if backends := strings.TrimSpace(os.Getenv("BITRIVER_TRANSCODER_BACKENDS")); backends != "" {
+		parsed, err := parseTranscoderBackends(backends)
+		if err != nil {
+			return Config{}, fmt.Errorf("parse BITRIVER_TRANSCODER_BACKENDS: %w", err)
+		}
+		cfg.Transcoders = parsed
+	}
+
 	if ladder := strings.TrimSpace(os.Getenv("BITRIVER_TRANSCODE_LADDER")); ladder != "" {
 		profiles, err := parseLadder(ladder)
 		if err != nil {
@@ -112,6 +159,25 @@ func LoadConfigFromEnv() (Config, error) {
 		}
 	}
 
+	for _, limit := range []struct {
+		env    string
+		target *int
+	}{
+		{"BITRIVER_TRANSCODE_MAX_HEIGHT", &cfg.TranscodeLimits.MaxHeight},
+		{"BITRIVER_TRANSCODE_MAX_BITRATE", &cfg.TranscodeLimits.MaxBitrate},
+		{"BITRIVER_TRANSCODE_MAX_RENDITIONS", &cfg.TranscodeLimits.MaxRenditions},
+	} {
+		raw := strings.TrimSpace(os.Getenv(limit.env))
+		if raw == "" {
+			continue
+		}
+		parsed, err := strconv.Atoi(raw)
+		if err != nil || parsed < 0 {
+			return Config{}, fmt.Errorf("parse %s: must be a non-negative integer", limit.env)
+		}
+		*limit.target = parsed
+	}
+
 	if cfg.HealthEndpoint == "" {
 		cfg.HealthEndpoint = "/healthz"
 	}
@@ -147,6 +213,121 @@ func parseLadder(spec string) ([]Rendition, error) {
 	return results, nil
 }
 
+// TranscoderBackend is one transcoder service that jobs can be placed on.
+type TranscoderBackend struct {
+	// Name identifies the backend in job handles, health output, and logs.
+	// It cannot contain a colon.
+	Name    string
+	BaseURL string
+	// Token authenticates requests to the backend. Empty uses
+	// Config.JobToken.
+	Token string
+	// Weight is the backend's share of placements relative to the other
+	// backends. Zero counts as 1.
+	Weight int
+	// Labels are free-form tags, such as a region or GPU model, logged
+	// with each job placed on the backend.
+	Labels map[string]string
+}
+
+// parseTranscoderBackends parses a comma-separated list of backends, each
+// written as name=url followed by optional ;key=value settings. The keys
+// weight and token set those fields; any other key becomes a label, as in
+// "east=http://t1:9000;weight=2;region=us-east,west=http://t2:9000".
+func parseTranscoderBackends(spec string) ([]TranscoderBackend, error) {
+	var backends []TranscoderBackend
+	for _, entry := range strings.Split(spec, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		parts := strings.Split(entry, ";")
+		name, baseURL, ok := strings.Cut(parts[0], "=")
+		if !ok {
+			return nil, fmt.Errorf("invalid transcoder backend %q: want name=url", entry)
+		}
+		backend := TranscoderBackend{Name: strings.TrimSpace(name), BaseURL: strings.TrimSpace(baseURL)}
+		for _, setting := range parts[1:] {
+			key, value, ok := strings.Cut(setting, "=")
+			key, value = strings.TrimSpace(key), strings.TrimSpace(value)
+			if !ok || key == "" {
+				return nil, fmt.Errorf("invalid setting %q for transcoder backend %q", setting, backend.Name)
+			}
+			switch key {
+			case "weight":
+				weight, err := strconv.Atoi(value)
+				if err != nil {
+					return nil, fmt.Errorf("invalid weight for transcoder backend %q: %w", backend.Name, err)
+				}
+				backend.Weight = weight
+			case "token":
+				backend.Token = value
+			default:
+				if backend.Labels == nil {
+					backend.Labels = make(map[string]string)
+				}
+				backend.Labels[key] = value
+			}
+		}
+		backends = append(backends, backend)
+	}
+	if len(backends) == 0 {
+		return nil, errors.New("no transcoder backends configured")
+	}
+	return backends, nil
+}
+
+func validateTranscoderBackends(backends []TranscoderBackend) error {
+	seen := make(map[string]bool, len(backends))
+	for _, backend := range backends {
+		switch {
+		case backend.Name == "":
+			return errors.New("transcoder backend name is required")
+		case strings.Contains(backend.Name, ":"):
+			return fmt.Errorf("transcoder backend name %q cannot contain a colon", backend.Name)
+		case seen[backend.Name]:
+			return fmt.Errorf("duplicate transcoder backend %q", backend.Name)
+		case backend.BaseURL == "":
+			return fmt.Errorf("transcoder backend %q has no base URL", backend.Name)
+		case backend.Weight < 0:
+			return fmt.Errorf("transcoder backend %q weight cannot be negative", backend.Name)
+		}
+		seen[backend.Name] = true
+	}
+	return nil
+}
+
+// transcoderBackends returns Transcoders with defaults applied, or a single
+// backend named "default" built from JobBaseURL and JobToken.
+func (c Config) transcoderBackends() []TranscoderBackend {
+	if len(c.Transcoders) == 0 {
+		return []TranscoderBackend{{Name: defaultTranscoderBackend, BaseURL: c.JobBaseURL, Token: c.JobToken, Weight: 1}}
+	}
+	backends := make([]TranscoderBackend, len(c.Transcoders))
+	for i, backend := range c.Transcoders {
+		if backend.Token == "" {
+			backend.Token = c.JobToken
+		}
+		if backend.Weight <= 0 {
+			backend.Weight = 1
+		}
+		backends[i] = backend
+	}
+	return backends
+}
+
+func (c Config) everyTranscoderHasToken() bool {
+	if len(c.Transcoders) == 0 {
+		return false
+	}
+	for _, backend := range c.Transcoders {
+		if backend.Token == "" {
+			return false
+		}
+	}
+	return true
+}
+
 // Enabled reports whether enough configuration has been provided to talk to
 // external ingest services.
 func (c Config) Enabled() bool {
@@ -185,13 +366,19 @@ func (c Config) Validate() error {
 	if c.HealthTimeout <= 0 {
 		return errors.New("health timeout must be positive")
 	}
-	return nil
+	if c.BootStepTimeout < 0 {
+		return errors.New("boot step timeout cannot be negative")
+	}
+	if c.BootCleanupReserve < 0 {
+		return errors.New("boot cleanup reserve cannot be negative")
+	}
+	return validateTranscoderBackends(c.Transcoders)
 }
 
 func (c Config) hasAnyConfig() bool {
 	return c.SRSBaseURL != "" || c.SRSToken != "" ||
 		c.OMEBaseURL != "" || c.OMEUsername != "" || c.OMEPassword != "" ||
-		c.JobBaseURL != "" || c.JobToken != ""
+		c.JobBaseURL != "" || c.JobToken != "" || len(c.Transcoders) > 0
 }
 
 func (c Config) missingRequiredFields() []string {
@@ -211,10 +398,10 @@ func (c Config) missingRequiredFields() []string {
 	if c.OMEPassword == "" {
 		missing = append(missing, "BITRIVER_OME_PASSWORD")
 	}
-	if c.JobBaseURL == "" {
+	if c.JobBaseURL == "" && len(c.Transcoders) == 0 {
 		missing = append(missing, "BITRIVER_TRANSCODER_API")
 	}
-	if c.JobToken == "" {
+	if c.JobToken == "" && !c.everyTranscoderHasToken() {
 		missing = append(missing, "BITRIVER_TRANSCODER_TOKEN")
 	}
 	return missing
@@ -225,7 +412,12 @@ func (c Config) NewHTTPController() (*HTTPController, error) {
 	if err := c.Validate(); err != nil {
 		return nil, err
 	}
-	controller := &HTTPController{config: c, retryAttempts: c.HTTPMaxAttempts, retryInterval: c.HTTPRetryInterval}
+	controller := &HTTPController{
+		config:        c,
+		transcoders:   newTranscoderPool(c.transcoderBackends()),
+		retryAttempts: c.HTTPMaxAttempts,
+		retryInterval: c.HTTPRetryInterval,
+	}
 	if controller.config.HTTPClient == nil {
 		controller.config.HTTPClient = &http.Client{Timeout: 10 * time.Second}
 	}
@@ -87,3 +87,57 @@ func TestConfigHTTPRetryOverrides(t *testing.T) {
 		t.Fatalf("expected HTTP retry interval override, got %s", cfg.HTTPRetryInterval)
 	}
 }
+
+func TestConfigTranscodeLimits(t *testing.T) {
+	t.Setenv("BITRIVER_SRS_API", "")
+	t.Setenv("BITRIVER_TRANSCODE_MAX_HEIGHT", "1080")
+	t.Setenv("BITRIVER_TRANSCODE_MAX_BITRATE", "9000")
+	t.Setenv("BITRIVER_TRANSCODE_MAX_RENDITIONS", "")
+
+	cfg, err := LoadConfigFromEnv()
+	if err != nil {
+		t.Fatalf("LoadConfigFromEnv: %v", err)
+	}
+	if cfg.TranscodeLimits.MaxHeight != 1080 || cfg.TranscodeLimits.MaxBitrate != 9000 || cfg.TranscodeLimits.MaxRenditions != 0 {
+		t.Fatalf("unexpected limits: %+v", cfg.TranscodeLimits)
+	}
+
+	t.Setenv("BITRIVER_TRANSCODE_MAX_RENDITIONS", "-1")
+	if _, err := LoadConfigFromEnv(); err == nil {
+		t.Fatal("expected a negative rendition cap to be rejected")
+	}
+}
+
+func TestConfigTranscoderBackends(t *testing.T) {
+	t.Setenv("BITRIVER_SRS_API", "http://srs:1985")
+	t.Setenv("BITRIVER_SRS_TOKEN", "secret")
+	t.Setenv("BITRIVER_OME_API", "http://ome:8081")
+	t.Setenv("BITRIVER_OME_USERNAME", "admin")
+	t.Setenv("BITRIVER_OME_PASSWORD", "password")
+	t.Setenv("BITRIVER_TRANSCODER_API", "")
+	t.Setenv("BITRIVER_TRANSCODER_TOKEN", "job-secret")
+	t.Setenv("BITRIVER_TRANSCODER_BACKENDS", "east=http://t1:9000;weight=3;region=us-east, west=http://t2:9000;token=west-secret")
+
+	cfg, err := LoadConfigFromEnv()
+	if err != nil {
+		t.Fatalf("LoadConfigFromEnv: %v", err)
+	}
+	backends := cfg.transcoderBackends()
+	if len(backends) != 2 {
+		t.Fatalf("expected two backends, got %+v", backends)
+	}
+	east, west := backends[0], backends[1]
+	if east.Name != "east" || east.BaseURL != "http://t1:9000" || east.Weight != 3 || east.Token != "job-secret" || east.Labels["region"] != "us-east" {
+		t.Fatalf("unexpected east backend: %+v", east)
+	}
+	if west.Name != "west" || west.Weight != 1 || west.Token != "west-secret" || len(west.Labels) != 0 {
+		t.Fatalf("unexpected west backend: %+v", west)
+	}
+
+	for _, spec := range []string{"east", "east=http://t1:9000;weight=x", "east=http://t1:9000,east=http://t2:9000", "a:b=http://t1:9000", "east=http://t1:9000;weight=-1"} {
+		t.Setenv("BITRIVER_TRANSCODER_BACKENDS", spec)
+		if _, err := LoadConfigFromEnv(); err == nil {
+			t.Fatalf("expected %q to be rejected", spec)
+		}
+	}
+}
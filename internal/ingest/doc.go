@@ -38,6 +38,8 @@
 //       per-channel application. This returns:
 //         • OriginURL: Pull URL for the transcoder.
 //         • PlaybackURL: HLS or CMAF URL for viewers.
+//         • PlaybackEndpoints: every WebRTC, LL-HLS, and HLS URL the
+//           application serves, ranked so players can fall back in order.
 //
 //   - StartJobs:
 //       The transcoderAdapter starts transcoding jobs using the OriginURL,
@@ -0,0 +1,339 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"bitriver-live/internal/observability/metrics"
+)
+
+const (
+	defaultHealthPollInterval   = 30 * time.Second
+	defaultHealthPollMaxBackoff = 5 * time.Minute
+	defaultHealthPollTimeout    = 5 * time.Second
+)
+
+// ErrHealthPollerStopped is returned by HealthPoller.Refresh after Shutdown.
+var ErrHealthPollerStopped = errors.New("ingest health poller stopped")
+
+// HealthPollerConfig tunes a HealthPoller. Zero values fall back to the
+// package defaults.
+type HealthPollerConfig struct {
+	// Controller is probed for health. Nil polls a NoopController.
+	Controller Controller
+	// Record receives the full set of statuses after every poll, such as
+	// storage.Repository.RecordIngestHealth.
+	Record func([]HealthStatus)
+	// Interval is how often healthy dependencies are probed.
+	Interval time.Duration
+	// MaxBackoff caps the delay between probes of a failing dependency,
+	// which doubles after each consecutive failure.
+	MaxBackoff time.Duration
+	// Timeout bounds a single probe. A probe still running when it expires
+	// is reported as an error.
+	Timeout time.Duration
+	Logger  *slog.Logger
+	// Now returns the current time. Tests inject a fake clock; nil falls
+	// back to time.Now.
+	Now func() time.Time
+}
+
+// healthTarget is a dependency, or the whole controller when it cannot
+// probe dependencies one at a time, along with its backoff state.
+type healthTarget struct {
+	component string
+	statuses  []HealthStatus
+	failures  int
+	nextProbe time.Time
+}
+
+// HealthPoller probes ingest dependencies in the background so health
+// endpoints can answer from the recorded snapshot instead of waiting on
+// upstream services. A dependency that keeps failing is probed less often,
+// with exponential backoff, until it recovers.
+type HealthPoller struct {
+	controller Controller
+	record     func([]HealthStatus)
+	interval   time.Duration
+	maxBackoff time.Duration
+	timeout    time.Duration
+	logger     *slog.Logger
+	now        func() time.Time
+
+	// polling admits one poll at a time so a refresh never overlaps the
+	// background loop.
+	polling chan struct{}
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      sync.WaitGroup
+
+	mu      sync.Mutex
+	targets []*healthTarget
+	started bool
+}
+
+// NewHealthPoller configures a poller. Call Start to begin polling.
+func NewHealthPoller(cfg HealthPollerConfig) *HealthPoller {
+	p := &HealthPoller{
+		controller: cfg.Controller,
+		record:     cfg.Record,
+		interval:   cfg.Interval,
+		maxBackoff: cfg.MaxBackoff,
+		timeout:    cfg.Timeout,
+		logger:     cfg.Logger,
+		now:        cfg.Now,
+		polling:    make(chan struct{}, 1),
+	}
+	if p.controller == nil {
+		p.controller = NoopController{}
+	}
+	if p.interval <= 0 {
+		p.interval = defaultHealthPollInterval
+	}
+	if p.maxBackoff <= 0 {
+		p.maxBackoff = defaultHealthPollMaxBackoff
+	}
+	if p.maxBackoff < p.interval {
+		p.maxBackoff = p.interval
+	}
+	if p.timeout <= 0 {
+		p.timeout = defaultHealthPollTimeout
+	}
+	if p.logger == nil {
+		p.logger = slog.Default()
+	}
+	if p.now == nil {
+		p.now = time.Now
+	}
+	if checker, ok := p.controller.(ComponentHealthChecker); ok {
+		for _, component := range checker.HealthComponents() {
+			p.targets = append(p.targets, &healthTarget{component: component})
+		}
+	}
+	if len(p.targets) == 0 {
+		p.targets = []*healthTarget{{}}
+	}
+	p.ctx, p.cancel = context.WithCancel(context.Background())
+	return p
+}
+
+// Start polls once immediately and then keeps polling until Shutdown.
+func (p *HealthPoller) Start() {
+	if p == nil {
+		return
+	}
+	p.mu.Lock()
+	if p.started {
+		p.mu.Unlock()
+		return
+	}
+	p.started = true
+	p.mu.Unlock()
+
+	p.wg.Add(1)
+	go p.loop()
+}
+
+// Shutdown stops polling and waits for a poll in progress to finish, or
+// for ctx to end.
+func (p *HealthPoller) Shutdown(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	p.cancel()
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// Refresh probes every dependency now, ignoring backoff, and records the
+// result before returning.
+func (p *HealthPoller) Refresh(ctx context.Context) error {
+	if p == nil {
+		return ErrHealthPollerStopped
+	}
+	if p.ctx.Err() != nil {
+		return ErrHealthPollerStopped
+	}
+	return p.poll(ctx, true)
+}
+
+func (p *HealthPoller) loop() {
+	defer p.wg.Done()
+	for {
+		if err := p.poll(p.ctx, false); err != nil {
+			return
+		}
+		timer := time.NewTimer(p.untilNextProbe())
+		select {
+		case <-p.ctx.Done():
+			timer.Stop()
+			return
+		case <-timer.C:
+		}
+	}
+}
+
+// untilNextProbe is how long the loop can sleep before a dependency is due.
+func (p *HealthPoller) untilNextProbe() time.Duration {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	now := p.now()
+	wait := p.maxBackoff
+	for _, target := range p.targets {
+		if until := target.nextProbe.Sub(now); until < wait {
+			wait = until
+		}
+	}
+	if wait < 0 {
+		wait = 0
+	}
+	return wait
+}
+
+// poll probes the dependencies that are due, or all of them when force is
+// set, concurrently so one slow upstream does not hold up the others. It
+// then records the combined statuses and publishes them as metrics.
+func (p *HealthPoller) poll(ctx context.Context, force bool) error {
+	select {
+	case p.polling <- struct{}{}:
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+	defer func() { <-p.polling }()
+
+	p.mu.Lock()
+	now := p.now()
+	var due []*healthTarget
+	for _, target := range p.targets {
+		if force || !now.Before(target.nextProbe) {
+			due = append(due, target)
+		}
+	}
+	p.mu.Unlock()
+
+	probeCtx, cancel := context.WithTimeout(ctx, p.timeout)
+	defer cancel()
+	pending := make([]chan []HealthStatus, len(due))
+	for i, target := range due {
+		pending[i] = make(chan []HealthStatus, 1)
+		go func(done chan<- []HealthStatus, component string) {
+			done <- p.probe(probeCtx, component)
+		}(pending[i], target.component)
+	}
+	results := make([][]HealthStatus, len(due))
+	for i, target := range due {
+		select {
+		case results[i] = <-pending[i]:
+		case <-probeCtx.Done():
+			// The probe ignored its deadline; report it without waiting
+			// any longer.
+			results[i] = []HealthStatus{{Component: componentName(target.component), Status: "error", Detail: "health check timed out"}}
+		}
+	}
+	if err := ctx.Err(); err != nil {
+		// Cut short by the caller rather than by the probe timeout; keep
+		// the previous results.
+		return err
+	}
+
+	p.mu.Lock()
+	now = p.now()
+	for i, target := range due {
+		target.statuses = results[i]
+		if failing(results[i]) {
+			target.failures++
+			if target.failures == 1 {
+				p.logger.Warn("ingest dependency unhealthy", "component", componentName(target.component), "detail", firstDetail(results[i]))
+			}
+		} else {
+			if target.failures > 0 {
+				p.logger.Info("ingest dependency recovered", "component", componentName(target.component))
+			}
+			target.failures = 0
+		}
+		target.nextProbe = now.Add(p.backoff(target.failures))
+	}
+	statuses := make([]HealthStatus, 0, len(p.targets))
+	for _, target := range p.targets {
+		statuses = append(statuses, target.statuses...)
+	}
+	p.mu.Unlock()
+
+	if p.record != nil {
+		p.record(statuses)
+	}
+	for _, status := range statuses {
+		metrics.SetIngestHealth(status.Component, status.Status)
+	}
+	return nil
+}
+
+// probe checks one dependency, or the whole controller when component is
+// empty.
+func (p *HealthPoller) probe(ctx context.Context, component string) []HealthStatus {
+	if component != "" {
+		if checker, ok := p.controller.(ComponentHealthChecker); ok {
+			return []HealthStatus{checker.CheckComponentHealth(ctx, component)}
+		}
+	}
+	statuses := p.controller.HealthChecks(ctx)
+	if len(statuses) == 0 {
+		statuses = []HealthStatus{{Component: "ingest", Status: "unknown"}}
+	}
+	return statuses
+}
+
+// backoff is the delay before the next probe after failures consecutive
+// failures: the interval, doubled per failure, capped at maxBackoff.
+func (p *HealthPoller) backoff(failures int) time.Duration {
+	delay := p.interval
+	for i := 0; i < failures && delay < p.maxBackoff; i++ {
+		delay *= 2
+	}
+	if delay > p.maxBackoff {
+		delay = p.maxBackoff
+	}
+	return delay
+}
+
+// failing reports whether any status is an error. Dependencies that are
+// disabled or not configured are not retried any sooner by backing off.
+func failing(statuses []HealthStatus) bool {
+	for _, status := range statuses {
+		switch strings.ToLower(status.Status) {
+		case "ok", "disabled", "unknown":
+		default:
+			return true
+		}
+	}
+	return false
+}
+
+func firstDetail(statuses []HealthStatus) string {
+	for _, status := range statuses {
+		if status.Detail != "" {
+			return status.Detail
+		}
+	}
+	return ""
+}
+
+func componentName(component string) string {
+	if component == "" {
+		return "ingest"
+	}
+	return component
+}
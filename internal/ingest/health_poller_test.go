@@ -0,0 +1,225 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+// fakeHealthClock is a manually advanced clock for the poller.
+type fakeHealthClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeHealthClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeHealthClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+// componentHealthStub reports a fixed status per component, records when
+// each one is probed, and can hang probes, ignoring their deadline, until
+// released.
+type componentHealthStub struct {
+	NoopController
+	clock *fakeHealthClock
+
+	mu     sync.Mutex
+	status map[string]string
+	probes map[string][]time.Time
+	hang   map[string]chan struct{}
+}
+
+func newComponentHealthStub(clock *fakeHealthClock, status map[string]string) *componentHealthStub {
+	return &componentHealthStub{clock: clock, status: status, probes: make(map[string][]time.Time), hang: make(map[string]chan struct{})}
+}
+
+func (s *componentHealthStub) HealthComponents() []string {
+	return []string{"srs", "transcoder"}
+}
+
+func (s *componentHealthStub) CheckComponentHealth(ctx context.Context, component string) HealthStatus {
+	s.mu.Lock()
+	if s.clock != nil {
+		s.probes[component] = append(s.probes[component], s.clock.Now())
+	}
+	status := s.status[component]
+	hang := s.hang[component]
+	s.mu.Unlock()
+	if hang != nil {
+		<-hang
+	}
+	return HealthStatus{Component: component, Status: status}
+}
+
+func (s *componentHealthStub) setStatus(component, status string) {
+	s.mu.Lock()
+	s.status[component] = status
+	s.mu.Unlock()
+}
+
+func (s *componentHealthStub) probeOffsets(component string, start time.Time) []time.Duration {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	offsets := make([]time.Duration, 0, len(s.probes[component]))
+	for _, at := range s.probes[component] {
+		offsets = append(offsets, at.Sub(start))
+	}
+	return offsets
+}
+
+func equalDurations(a, b []time.Duration) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestHealthPollerBacksOffFailingComponent(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := &fakeHealthClock{now: start}
+	stub := newComponentHealthStub(clock, map[string]string{"srs": "ok", "transcoder": "error"})
+	var recorded [][]HealthStatus
+	poller := NewHealthPoller(HealthPollerConfig{
+		Controller: stub,
+		Record:     func(statuses []HealthStatus) { recorded = append(recorded, statuses) },
+		Interval:   10 * time.Second,
+		MaxBackoff: 40 * time.Second,
+		Now:        clock.Now,
+	})
+
+	ctx := context.Background()
+	for elapsed := time.Duration(0); elapsed <= 140*time.Second; elapsed += 10 * time.Second {
+		if elapsed == 110*time.Second {
+			stub.setStatus("transcoder", "ok")
+		}
+		if err := poller.poll(ctx, false); err != nil {
+			t.Fatalf("poll: %v", err)
+		}
+		clock.Advance(10 * time.Second)
+	}
+
+	// Each failure doubles the wait to 20s, then 40s, where it is capped;
+	// the probe at 140s sees the recovery that happened at 110s.
+	want := []time.Duration{0, 20 * time.Second, 60 * time.Second, 100 * time.Second, 140 * time.Second}
+	if got := stub.probeOffsets("transcoder", start); !equalDurations(got, want) {
+		t.Fatalf("expected transcoder probed at %v, got %v", want, got)
+	}
+	if err := poller.poll(ctx, false); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if got := stub.probeOffsets("transcoder", start); got[len(got)-1] != 150*time.Second {
+		t.Fatalf("expected recovered transcoder back on the 10s interval, got %v", got)
+	}
+	if got := stub.probeOffsets("srs", start); len(got) != 16 {
+		t.Fatalf("expected healthy srs probed every interval, got %v", got)
+	}
+
+	if len(recorded) != 16 {
+		t.Fatalf("expected a snapshot per poll, got %d", len(recorded))
+	}
+	// At 130s the transcoder was still backing off, so its last failure is
+	// carried over.
+	if snapshot := recorded[13]; len(snapshot) != 2 || snapshot[0] != (HealthStatus{Component: "srs", Status: "ok"}) || snapshot[1] != (HealthStatus{Component: "transcoder", Status: "error"}) {
+		t.Fatalf("expected the backed-off status carried into the snapshot, got %+v", snapshot)
+	}
+}
+
+func TestHealthPollerReportsHungProbeAfterTimeout(t *testing.T) {
+	stub := newComponentHealthStub(nil, map[string]string{"srs": "ok", "transcoder": "ok"})
+	release := make(chan struct{})
+	defer close(release)
+	stub.hang["transcoder"] = release
+	var recorded []HealthStatus
+	poller := NewHealthPoller(HealthPollerConfig{
+		Controller: stub,
+		Record:     func(statuses []HealthStatus) { recorded = statuses },
+		Timeout:    50 * time.Millisecond,
+	})
+
+	started := time.Now()
+	if err := poller.poll(context.Background(), false); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if elapsed := time.Since(started); elapsed > time.Second {
+		t.Fatalf("expected the poll to give up on the hung probe, took %v", elapsed)
+	}
+	if len(recorded) != 2 || recorded[0].Status != "ok" || recorded[1].Status != "error" || recorded[1].Detail != "health check timed out" {
+		t.Fatalf("expected the hung transcoder reported as timed out, got %+v", recorded)
+	}
+}
+
+func TestHealthPollerRefreshIgnoresBackoff(t *testing.T) {
+	start := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	clock := &fakeHealthClock{now: start}
+	stub := newComponentHealthStub(clock, map[string]string{"srs": "error", "transcoder": "ok"})
+	poller := NewHealthPoller(HealthPollerConfig{Controller: stub, Interval: time.Minute, Now: clock.Now})
+
+	ctx := context.Background()
+	if err := poller.poll(ctx, false); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	clock.Advance(time.Second)
+	if err := poller.poll(ctx, false); err != nil {
+		t.Fatalf("poll: %v", err)
+	}
+	if got := stub.probeOffsets("srs", start); len(got) != 1 {
+		t.Fatalf("expected no probe before the interval, got %v", got)
+	}
+
+	stub.setStatus("srs", "ok")
+	if err := poller.Refresh(ctx); err != nil {
+		t.Fatalf("Refresh: %v", err)
+	}
+	want := []time.Duration{0, time.Second}
+	for _, component := range []string{"srs", "transcoder"} {
+		if got := stub.probeOffsets(component, start); !equalDurations(got, want) {
+			t.Fatalf("expected %s probed at %v, got %v", component, want, got)
+		}
+	}
+}
+
+func TestHealthPollerShutdownStopsPolling(t *testing.T) {
+	stub := newComponentHealthStub(nil, map[string]string{"srs": "ok", "transcoder": "ok"})
+	polled := make(chan struct{}, 1)
+	poller := NewHealthPoller(HealthPollerConfig{
+		Controller: stub,
+		Record: func([]HealthStatus) {
+			select {
+			case polled <- struct{}{}:
+			default:
+			}
+		},
+		Interval: time.Hour,
+	})
+	poller.Start()
+
+	select {
+	case <-polled:
+	case <-time.After(5 * time.Second):
+		t.Fatal("expected the poller to poll on start")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	if err := poller.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if err := poller.Refresh(context.Background()); !errors.Is(err, ErrHealthPollerStopped) {
+		t.Fatalf("expected Refresh after Shutdown to fail with ErrHealthPollerStopped, got %v", err)
+	}
+}
@@ -2,12 +2,14 @@ package ingest
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"io"
 	"log/slog"
 	"net/http"
 	"strings"
+	"sync"
 	"time"
 
 	"bitriver-live/internal/observability/metrics"
@@ -28,10 +30,13 @@ import (
 // used concurrently; configuration methods (such as SetLogger) should be
 // called before concurrent use.
 type HTTPController struct {
-	config        Config
-	channels      channelAdapter
-	applications  applicationAdapter
-	transcoder    transcoderAdapter
+	config       Config
+	channels     channelAdapter
+	applications applicationAdapter
+	transcoder   transcoderAdapter
+	// transcoders tracks the health and load of the transcoder backends.
+	// Health probes update it, so it is created before concurrent use.
+	transcoders   *transcoderPool
 	logger        *slog.Logger
 	retryAttempts int
 	retryInterval time.Duration
@@ -74,10 +79,12 @@ func (c *HTTPController) ensureAdapters() {
 			c.retryInterval,
 		)
 	}
+	if c.transcoders == nil {
+		c.transcoders = newTranscoderPool(c.config.transcoderBackends())
+	}
 	if c.transcoder == nil {
-		c.transcoder = newHTTPTranscoderAdapter(
-			c.config.JobBaseURL,
-			c.config.JobToken,
+		c.transcoder = newTranscoderRouter(
+			c.transcoders,
 			c.config.HTTPClient,
 			c.logger,
 			c.retryAttempts,
@@ -121,13 +128,22 @@ func (c *HTTPController) SetLogger(logger *slog.Logger) {
 // BootStream initializes a complete ingest pipeline for a live stream.
 //
 // The operation:
-//  1. Provisions a channel in SRS (primary/backup ingest endpoints).
-//  2. Creates an OME application (origin + playback URLs).
-//  3. Starts transcoding jobs using the configured rendition ladder.
+//  1. Provisions a channel in SRS (primary/backup ingest endpoints) and,
+//     concurrently, creates an OME application (origin + playback URLs).
+//  2. Starts transcoding jobs against the OME origin using the configured
+//     rendition ladder, capped by Config.TranscodeLimits with the channel's
+//     overrides applied.
+//  3. Starts a restream for each of params.Restreams.
 //
-// On failure, BootStream attempts to roll back previously created
-// resources (e.g., deleting the OME application if transcoder startup
-// fails).
+// When ctx carries a deadline, the time left, less Config.BootCleanupReserve,
+// is divided across the steps still to run, so a slow first step leaves the
+// rest of the budget to the next rather than a fixed share. Each step is
+// also capped at Config.BootStepTimeout, so one slow service cannot spend
+// the whole deadline on retries. A failed step is reported as
+// *BootStepError, after the channel and application that were created are
+// deleted again on the reserved slice, which runs even once ctx has
+// expired. Restreams are best-effort: failing to start them is logged and
+// leaves the primary pipeline running.
 //
 // Callers should provide a context with an appropriate deadline to bound
 // the overall latency of the operation.
@@ -145,63 +161,224 @@ func (c *HTTPController) BootStream(ctx context.Context, params BootParams) (Boo
 		"session_id", params.SessionID,
 	)
 
-	primary, backup, err := c.channels.CreateChannel(ctx, params.ChannelID, params.StreamKey)
-	if err != nil {
-		c.logger.Error("failed to create SRS channel",
-			"channel_id", params.ChannelID,
-			"error", err,
-		)
-		metrics.ObserveIngestFailure("boot_stream")
-		return BootResult{}, err
-	}
-
-	origin, playback, err := c.applications.CreateApplication(ctx, params.ChannelID, params.Renditions)
-	if err != nil {
-		c.logger.Error("failed to create OME application",
-			"channel_id", params.ChannelID,
-			"error", err,
-		)
-		_ = c.channels.DeleteChannel(ctx, params.ChannelID)
+	var (
+		wg                 sync.WaitGroup
+		primary, backup    string
+		application        applicationResult
+		channelErr, appErr error
+	)
+	// The channel and application are created in parallel, so they share
+	// the first of the two boot steps.
+	provisionTimeout := c.stepTimeout(ctx, 2)
+	wg.Add(2)
+	go func() {
+		defer wg.Done()
+		stepCtx, cancel := context.WithTimeout(ctx, provisionTimeout)
+		defer cancel()
+		primary, backup, channelErr = c.channels.CreateChannel(stepCtx, params.ChannelID, params.StreamKey)
+	}()
+	go func() {
+		defer wg.Done()
+		stepCtx, cancel := context.WithTimeout(ctx, provisionTimeout)
+		defer cancel()
+		application, appErr = c.applications.CreateApplication(stepCtx, params.ChannelID, params.Renditions)
+	}()
+	wg.Wait()
+
+	if channelErr != nil || appErr != nil {
+		var errs []error
+		if channelErr != nil {
+			c.logger.Error("failed to create SRS channel",
+				"channel_id", params.ChannelID,
+				"error", channelErr,
+			)
+			errs = append(errs, &BootStepError{Step: BootStepChannel, Err: channelErr})
+		}
+		if appErr != nil {
+			c.logger.Error("failed to create OME application",
+				"channel_id", params.ChannelID,
+				"error", appErr,
+			)
+			errs = append(errs, &BootStepError{Step: BootStepApplication, Err: appErr})
+		}
+		c.rollbackBoot(ctx, params.ChannelID, channelErr == nil, appErr == nil)
 		metrics.ObserveIngestFailure("boot_stream")
-		return BootResult{}, err
+		if len(errs) == 1 {
+			return BootResult{}, errs[0]
+		}
+		return BootResult{}, errors.Join(errs...)
 	}
 
-	jobIDs, renditions, err := c.transcoder.StartJobs(ctx, params.ChannelID, params.SessionID, origin, c.config.LadderProfiles)
+	limits := c.config.TranscodeLimits.WithOverride(params.TranscodeLimits)
+	jobsCtx, cancelJobs := context.WithTimeout(ctx, c.stepTimeout(ctx, 1))
+	origin := application.OriginURL
+	jobIDs, renditions, err := c.transcoder.StartJobs(jobsCtx, params.ChannelID, params.SessionID, origin, c.config.LadderProfiles, limits)
+	cancelJobs()
 	if err != nil {
 		c.logger.Error("failed to start transcoder jobs",
 			"channel_id", params.ChannelID,
 			"session_id", params.SessionID,
 			"error", err,
 		)
-		_ = c.applications.DeleteApplication(ctx, params.ChannelID)
-		_ = c.channels.DeleteChannel(ctx, params.ChannelID)
+		c.rollbackBoot(ctx, params.ChannelID, true, true)
 		metrics.ObserveIngestFailure("boot_stream")
-		return BootResult{}, err
+		return BootResult{}, &BootStepError{Step: BootStepJobs, Err: err}
 	}
 
+	if len(params.Restreams) > 0 {
+		restreamCtx, cancelRestreams := context.WithTimeout(ctx, c.stepTimeout(ctx, 1))
+		err := c.transcoder.StartRestreams(restreamCtx, params.ChannelID, params.SessionID, origin, params.Restreams)
+		cancelRestreams()
+		if err != nil {
+			c.logger.Warn("failed to start restreams",
+				"channel_id", params.ChannelID,
+				"session_id", params.SessionID,
+				"targets", len(params.Restreams),
+				"error", err,
+			)
+		}
+	}
+
+	placement := c.jobBackend(jobIDs)
 	c.logger.Info("ingest pipeline ready",
 		"channel_id", params.ChannelID,
 		"session_id", params.SessionID,
 		"jobs", len(jobIDs),
+		"transcoder_backend", placement,
 	)
 
 	return BootResult{
-		PrimaryIngest: primary,
-		BackupIngest:  backup,
-		OriginURL:     origin,
-		PlaybackURL:   playback,
-		Renditions:    renditions,
-		JobIDs:        jobIDs,
+		PrimaryIngest:     primary,
+		BackupIngest:      backup,
+		OriginURL:         origin,
+		PlaybackURL:       application.PlaybackURL,
+		PlaybackEndpoints: application.PlaybackEndpoints,
+		Renditions:        renditions,
+		JobIDs:            jobIDs,
+		PreviewURL:        LivePreviewURL(c.backendJobIDs(jobIDs), renditions),
+		TranscoderBackend: placement,
 	}, nil
 }
 
+// jobBackend names the transcoder backend that owns jobIDs, or returns ""
+// when the transcoder adapter does not route between backends.
+func (c *HTTPController) jobBackend(jobIDs []string) string {
+	router, ok := c.transcoder.(*transcoderRouter)
+	if !ok || len(jobIDs) == 0 {
+		return ""
+	}
+	return router.backendOf(jobIDs[0])
+}
+
+// backendJobIDs strips the backend names from job handles, leaving the IDs
+// the transcoder itself uses in its URLs.
+func (c *HTTPController) backendJobIDs(handles []string) []string {
+	router, ok := c.transcoder.(*transcoderRouter)
+	if !ok {
+		return handles
+	}
+	jobIDs := make([]string, len(handles))
+	for i, handle := range handles {
+		jobIDs[i] = router.jobID(handle)
+	}
+	return jobIDs
+}
+
+// bootStepTimeout bounds each BootStream step, and each rollback when the
+// caller set no deadline.
+func (c *HTTPController) bootStepTimeout() time.Duration {
+	if c.config.BootStepTimeout > 0 {
+		return c.config.BootStepTimeout
+	}
+	return defaultBootStepTimeout
+}
+
+// bootCleanupReserve is the slice of the caller's deadline kept back for
+// rolling back a failed boot.
+func (c *HTTPController) bootCleanupReserve() time.Duration {
+	if c.config.BootCleanupReserve > 0 {
+		return c.config.BootCleanupReserve
+	}
+	return defaultBootCleanupReserve
+}
+
+// stepTimeout is how long the next of steps remaining boot steps may run:
+// an even share of the time left before ctx's deadline, after the cleanup
+// reserve, capped at bootStepTimeout. Without a deadline every step gets
+// bootStepTimeout. A share of zero or less fails the step at once.
+func (c *HTTPController) stepTimeout(ctx context.Context, steps int) time.Duration {
+	timeout := c.bootStepTimeout()
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return timeout
+	}
+	if steps < 1 {
+		steps = 1
+	}
+	if share := (time.Until(deadline) - c.bootCleanupReserve()) / time.Duration(steps); share < timeout {
+		return share
+	}
+	return timeout
+}
+
+// rollbackBoot deletes the SRS channel and OME application a failed boot
+// created, in parallel so a slow delete cannot starve the other. It runs
+// detached from ctx, so a boot that failed because ctx expired still cleans
+// up after itself: on the cleanup reserve when ctx has a deadline, otherwise
+// on bootStepTimeout.
+func (c *HTTPController) rollbackBoot(ctx context.Context, channelID string, channel, application bool) {
+	timeout := c.bootStepTimeout()
+	if _, ok := ctx.Deadline(); ok {
+		timeout = c.bootCleanupReserve()
+	}
+	cleanupCtx, cancel := context.WithTimeout(context.WithoutCancel(ctx), timeout)
+	defer cancel()
+	var wg sync.WaitGroup
+	if application {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.applications.DeleteApplication(cleanupCtx, channelID); err != nil {
+				c.logger.Warn("failed to roll back OME application",
+					"channel_id", channelID,
+					"error", err,
+				)
+			}
+		}()
+	}
+	if channel {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if err := c.channels.DeleteChannel(cleanupCtx, channelID); err != nil {
+				c.logger.Warn("failed to roll back SRS channel",
+					"channel_id", channelID,
+					"error", err,
+				)
+			}
+		}()
+	}
+	wg.Wait()
+}
+
 // ShutdownStream tears down an ingest pipeline that was previously
 // initialized with BootStream.
 //
-// It best-effort stops each transcoder job, removes the OME application,
-// and deletes the SRS channel. All errors are aggregated and returned
-// as a single error if any step fails.
+// It best-effort stops the channel's restreams and each transcoder job,
+// removes the OME application, and deletes the SRS channel. A pipeline that
+// only partly booted is torn down as far as it exists: empty job IDs are
+// skipped and resources the services report as missing count as removed.
+// All other errors except restream failures, which are only logged, are
+// aggregated and returned as a single error.
 func (c *HTTPController) ShutdownStream(ctx context.Context, channelID, sessionID string, jobIDs []string) error {
+	_, err := c.ShutdownStreamOutput(ctx, channelID, sessionID, jobIDs)
+	return err
+}
+
+// ShutdownStreamOutput tears the pipeline down like ShutdownStream and
+// returns the combined output size the transcoder reported for the stopped
+// jobs. It implements StreamOutputReporter.
+func (c *HTTPController) ShutdownStreamOutput(ctx context.Context, channelID, sessionID string, jobIDs []string) (int64, error) {
 	metrics.ObserveIngestAttempt("shutdown_stream")
 	c.ensureAdapters()
 
@@ -212,18 +389,40 @@ func (c *HTTPController) ShutdownStream(ctx context.Context, channelID, sessionI
 	)
 
 	var errs []string
+	var outputBytes int64
+
+	if err := c.transcoder.StopRestreams(ctx, channelID); err != nil {
+		c.logger.Warn("failed to stop restreams",
+			"channel_id", channelID,
+			"error", err,
+		)
+	}
 
 	for _, jobID := range jobIDs {
-		if err := c.transcoder.StopJob(ctx, jobID); err != nil {
+		if strings.TrimSpace(jobID) == "" {
+			continue
+		}
+		size, err := c.transcoder.StopJob(ctx, jobID)
+		if errors.Is(err, ErrTranscoderBackendUnknown) {
+			// The backend was removed from the configuration, so its
+			// jobs cannot be reached; tear down the rest regardless.
+			c.logger.Warn("skipping transcoder job on unconfigured backend",
+				"job_id", jobID,
+				"error", err,
+			)
+			continue
+		}
+		if err != nil && !isNotFound(err) {
 			c.logger.Error("failed to stop transcoder job",
 				"job_id", jobID,
 				"error", err,
 			)
 			errs = append(errs, fmt.Sprintf("stop job %s: %v", jobID, err))
 		}
+		outputBytes += size
 	}
 
-	if err := c.applications.DeleteApplication(ctx, channelID); err != nil {
+	if err := c.applications.DeleteApplication(ctx, channelID); err != nil && !isNotFound(err) {
 		c.logger.Error("failed to delete OME application",
 			"channel_id", channelID,
 			"error", err,
@@ -231,7 +430,7 @@ func (c *HTTPController) ShutdownStream(ctx context.Context, channelID, sessionI
 		errs = append(errs, fmt.Sprintf("delete OME app: %v", err))
 	}
 
-	if err := c.channels.DeleteChannel(ctx, channelID); err != nil {
+	if err := c.channels.DeleteChannel(ctx, channelID); err != nil && !isNotFound(err) {
 		c.logger.Error("failed to delete SRS channel",
 			"channel_id", channelID,
 			"error", err,
@@ -241,14 +440,45 @@ func (c *HTTPController) ShutdownStream(ctx context.Context, channelID, sessionI
 
 	if len(errs) > 0 {
 		metrics.ObserveIngestFailure("shutdown_stream")
-		return errors.New(strings.Join(errs, "; "))
+		return 0, errors.New(strings.Join(errs, "; "))
 	}
 
 	c.logger.Info("ingest pipeline removed",
 		"channel_id", channelID,
 		"session_id", sessionID,
+		"output_bytes", outputBytes,
 	)
-	return nil
+	return outputBytes, nil
+}
+
+// CaptureFrame fetches the latest still frame the transcoder captured for
+// jobID. It implements FrameCapturer.
+func (c *HTTPController) CaptureFrame(ctx context.Context, jobID string) (Frame, error) {
+	if strings.TrimSpace(jobID) == "" {
+		return Frame{}, ErrFrameUnavailable
+	}
+	c.ensureAdapters()
+	return c.transcoder.FetchFrame(ctx, jobID)
+}
+
+// CaptureClip cuts the newest durationSeconds of jobID into a clip. It
+// implements LiveClipper.
+func (c *HTTPController) CaptureClip(ctx context.Context, jobID string, durationSeconds int) (LiveClip, error) {
+	if strings.TrimSpace(jobID) == "" {
+		return LiveClip{}, ErrClipUnavailable
+	}
+	c.ensureAdapters()
+	return c.transcoder.CreateClip(ctx, jobID, durationSeconds)
+}
+
+// RestreamStatus reports the state of each restream of channelID. It
+// implements RestreamReporter.
+func (c *HTTPController) RestreamStatus(ctx context.Context, channelID string) ([]RestreamStatus, error) {
+	if strings.TrimSpace(channelID) == "" {
+		return nil, fmt.Errorf("channelID is required")
+	}
+	c.ensureAdapters()
+	return c.transcoder.RestreamStatus(ctx, channelID)
 }
 
 // TranscodeUpload submits a stored media file for HLS (or similar) VOD
@@ -277,11 +507,12 @@ func (c *HTTPController) TranscodeUpload(ctx context.Context, params UploadTrans
 	)
 
 	result, err := c.transcoder.StartUpload(ctx, uploadJobRequest{
-		ChannelID:  params.ChannelID,
-		UploadID:   params.UploadID,
-		SourceURL:  source,
-		Filename:   strings.TrimSpace(params.Filename),
-		Renditions: CloneRenditions(params.Renditions),
+		ChannelID:    params.ChannelID,
+		UploadID:     params.UploadID,
+		SourceURL:    source,
+		Filename:     strings.TrimSpace(params.Filename),
+		ExpectedHash: strings.ToLower(strings.TrimSpace(params.ExpectedHash)),
+		Renditions:   CloneRenditions(params.Renditions),
 	})
 	if err != nil {
 		c.logger.Error("failed to start upload transcode",
@@ -300,18 +531,91 @@ func (c *HTTPController) TranscodeUpload(ctx context.Context, params UploadTrans
 	)
 
 	return UploadTranscodeResult{
-		PlaybackURL: result.PlaybackURL,
-		Renditions:  CloneRenditions(result.Renditions),
-		JobID:       result.JobID,
+		PlaybackURL:  result.PlaybackURL,
+		Renditions:   CloneRenditions(result.Renditions),
+		JobID:        result.JobID,
+		Media:        result.Media,
+		ThumbnailURL: result.ThumbnailURL,
 	}, nil
 }
 
+// UploadOutput reports whether the upload job jobID, as returned by
+// TranscodeUpload, has finished and how large its output is. It implements
+// UploadOutputReporter.
+func (c *HTTPController) UploadOutput(ctx context.Context, jobID string) (UploadOutput, error) {
+	if strings.TrimSpace(jobID) == "" {
+		return UploadOutput{}, ErrUploadOutputUnavailable
+	}
+	c.ensureAdapters()
+	return c.transcoder.UploadStatus(ctx, jobID)
+}
+
+// healthService is one HTTP service the ingest subsystem depends on.
+type healthService struct {
+	name string
+	base string
+	auth func(*http.Request)
+	// observe, when set, receives the result of each probe and the
+	// response body.
+	observe func(status HealthStatus, body []byte)
+}
+
+// maxHealthBodyBytes caps how much of a health response is read.
+const maxHealthBodyBytes = 64 << 10
+
+func (c *HTTPController) healthServices() []healthService {
+	services := []healthService{
+		{
+			name: "srs",
+			base: c.config.SRSBaseURL,
+			auth: bearerAuth(c.config.SRSToken),
+		},
+		{
+			name: "ovenmediaengine",
+			base: c.config.OMEBaseURL,
+			auth: basicAuth(c.config.OMEUsername, c.config.OMEPassword),
+		},
+	}
+	pool := c.transcoders
+	if pool == nil {
+		pool = newTranscoderPool(c.config.transcoderBackends())
+	}
+	for i, backend := range pool.backends {
+		i := i
+		services = append(services, healthService{
+			name: pool.component(i),
+			base: backend.BaseURL,
+			auth: bearerAuth(backend.Token),
+			observe: func(status HealthStatus, body []byte) {
+				pool.observe(i, status.Status == "ok", reportedRunningJobs(body))
+			},
+		})
+	}
+	return services
+}
+
+// reportedRunningJobs reads the runningJobs count the transcoder includes in
+// its health response, or returns -1 when it is missing.
+func reportedRunningJobs(body []byte) int {
+	var payload struct {
+		RunningJobs *int `json:"runningJobs"`
+	}
+	if err := json.Unmarshal(body, &payload); err != nil || payload.RunningJobs == nil {
+		return -1
+	}
+	return *payload.RunningJobs
+}
+
 // HealthChecks performs health probes against each of the underlying HTTP
 // services used by the ingest subsystem:
 //
 //   - SRS channel controller.
 //   - OvenMediaEngine application API.
-//   - Transcoder job service.
+//   - Transcoder job service, once per backend. A single backend is
+//     reported as "transcoder" and several as "transcoder:<name>". The
+//     results also decide which backends new jobs are placed on, and the
+//     runningJobs count in a transcoder's response steers placement
+//     toward the least loaded backend.
 //
 // Each service is probed at:
 //
@@ -323,83 +627,95 @@ func (c *HTTPController) TranscodeUpload(ctx context.Context, params UploadTrans
 func (c *HTTPController) HealthChecks(ctx context.Context) []HealthStatus {
 	c.ensureAdapters()
 
-	type service struct {
-		name string
-		base string
-		auth func(*http.Request)
-	}
-
-	services := []service{
-		{
-			name: "srs",
-			base: c.config.SRSBaseURL,
-			auth: bearerAuth(c.config.SRSToken),
-		},
-		{
-			name: "ovenmediaengine",
-			base: c.config.OMEBaseURL,
-			auth: basicAuth(c.config.OMEUsername, c.config.OMEPassword),
-		},
-		{
-			name: "transcoder",
-			base: c.config.JobBaseURL,
-			auth: bearerAuth(c.config.JobToken),
-		},
-	}
-
+	services := c.healthServices()
 	statuses := make([]HealthStatus, 0, len(services))
+	for _, svc := range services {
+		statuses = append(statuses, c.probeHealth(ctx, svc))
+	}
+	return statuses
+}
 
+// HealthComponents lists the services HealthChecks probes, in order.
+func (c *HTTPController) HealthComponents() []string {
+	services := c.healthServices()
+	names := make([]string, 0, len(services))
 	for _, svc := range services {
-		status := HealthStatus{Component: svc.name}
+		names = append(names, svc.name)
+	}
+	return names
+}
 
-		if strings.TrimSpace(svc.base) == "" {
-			status.Status = "unknown"
-			status.Detail = "base URL not configured"
-			statuses = append(statuses, status)
-			continue
+// CheckComponentHealth probes a single service named by HealthComponents.
+// Unlike HealthChecks it leaves the controller untouched, so probes of
+// different services may run concurrently.
+func (c *HTTPController) CheckComponentHealth(ctx context.Context, component string) HealthStatus {
+	for _, svc := range c.healthServices() {
+		if svc.name == component {
+			return c.probeHealth(ctx, svc)
 		}
+	}
+	return HealthStatus{Component: component, Status: "unknown", Detail: "unknown component"}
+}
 
-		url := fmt.Sprintf("%s%s", strings.TrimRight(svc.base, "/"), c.config.HealthEndpoint)
+func (c *HTTPController) probeHealth(ctx context.Context, svc healthService) HealthStatus {
+	if strings.TrimSpace(svc.base) == "" {
+		return HealthStatus{Component: svc.name, Status: "unknown", Detail: "base URL not configured"}
+	}
+	status, body := c.probeHealthURL(ctx, svc)
+	if svc.observe != nil {
+		svc.observe(status, body)
+	}
+	return status
+}
 
-		reqCtx, cancel := context.WithTimeout(ctx, c.config.HealthTimeout)
-		req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, url, nil)
-		if err != nil {
-			status.Status = "error"
-			status.Detail = err.Error()
-			statuses = append(statuses, status)
-			cancel()
-			continue
-		}
+// probeHealthURL requests the health endpoint of svc and returns the
+// resulting status along with the response body.
+func (c *HTTPController) probeHealthURL(ctx context.Context, svc healthService) (HealthStatus, []byte) {
+	status := HealthStatus{Component: svc.name}
 
-		if svc.auth != nil {
-			svc.auth(req)
-		}
+	url := fmt.Sprintf("%s%s", strings.TrimRight(svc.base, "/"), c.config.HealthEndpoint)
 
-		resp, err := c.config.HTTPClient.Do(req)
-		if err != nil {
-			status.Status = "error"
-			status.Detail = err.Error()
-			statuses = append(statuses, status)
-			cancel()
-			continue
-		}
+	client := c.config.HTTPClient
+	if client == nil {
+		client = &http.Client{Timeout: defaultHTTPTimeout}
+	}
+	timeout := c.config.HealthTimeout
+	if timeout <= 0 {
+		timeout = 2 * time.Second
+	}
 
-		// Fully drain and close the body to allow connection reuse.
-		_, _ = io.Copy(io.Discard, resp.Body)
-		_ = resp.Body.Close()
+	reqCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(reqCtx, http.MethodGet, url, nil)
+	if err != nil {
+		status.Status = "error"
+		status.Detail = err.Error()
+		return status, nil
+	}
 
-		if resp.StatusCode >= 200 && resp.StatusCode < 300 {
-			status.Status = "ok"
-		} else {
-			status.Status = "error"
-			status.Detail = resp.Status
-		}
+	if svc.auth != nil {
+		svc.auth(req)
+	}
 
-		cancel()
-		statuses = append(statuses, status)
+	resp, err := client.Do(req)
+	if err != nil {
+		status.Status = "error"
+		status.Detail = err.Error()
+		return status, nil
 	}
 
-	return statuses
+	// Fully drain and close the body to allow connection reuse.
+	body, _ := io.ReadAll(io.LimitReader(resp.Body, maxHealthBodyBytes))
+	_, _ = io.Copy(io.Discard, resp.Body)
+	_ = resp.Body.Close()
+
+	if resp.StatusCode >= 200 && resp.StatusCode < 300 {
+		status.Status = "ok"
+	} else {
+		status.Status = "error"
+		status.Detail = resp.Status
+	}
+	return status, body
 }
 
 // bearerAuth returns a request mutator that sets a Bearer token
@@ -0,0 +1,388 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/testsupport/ingeststub"
+)
+
+func faultTestController(stub *ingeststub.ControlPlane, attempts int, interval time.Duration) *HTTPController {
+	return &HTTPController{
+		config: Config{
+			SRSBaseURL:        stub.BaseURL(),
+			OMEBaseURL:        stub.BaseURL(),
+			JobBaseURL:        stub.BaseURL(),
+			HealthEndpoint:    "/healthz",
+			LadderProfiles:    []Rendition{{Name: "720p", Bitrate: 2400}},
+			HTTPRetryInterval: interval,
+			HTTPMaxAttempts:   attempts,
+		},
+	}
+}
+
+// assertRetryBackoff checks that every injected failure in journal was
+// retried no sooner than its injected latency plus the retry interval.
+func assertRetryBackoff(t *testing.T, journal []ingeststub.Operation, interval time.Duration) {
+	t.Helper()
+	for i := 1; i < len(journal); i++ {
+		prev := journal[i-1]
+		if !prev.Injected {
+			continue
+		}
+		if gap := journal[i].Timestamp.Sub(prev.Timestamp); gap < prev.Latency+interval {
+			t.Fatalf("%s attempt %d retried after %v, want >= %v", prev.Kind, prev.Attempt, gap, prev.Latency+interval)
+		}
+	}
+}
+
+func TestDoWithRetryUnderInjectedFlakiness(t *testing.T) {
+	const interval = 10 * time.Millisecond
+	stub := ingeststub.NewScenario(ingeststub.Options{}).
+		Script(ingeststub.EndpointChannelCreate, http.StatusInternalServerError, http.StatusTooManyRequests).
+		Latency(ingeststub.EndpointChannelCreate, ingeststub.LatencySequence(15*time.Millisecond, 5*time.Millisecond, 0)).
+		Start()
+	t.Cleanup(stub.Close)
+
+	start := time.Now()
+	var response struct {
+		PrimaryIngest string `json:"primaryIngest"`
+	}
+	body := []byte(`{"channelId":"channel-flaky","streamKey":"key"}`)
+	err := doWithRetry(context.Background(), http.DefaultClient, http.MethodPost, stub.BaseURL()+"/v1/channels", body, nil, &response, nil, 4, interval)
+	elapsed := time.Since(start)
+	if err != nil {
+		t.Fatalf("doWithRetry: %v", err)
+	}
+	if response.PrimaryIngest == "" {
+		t.Fatal("expected the successful attempt's response to be decoded")
+	}
+
+	journal := stub.Journal(ingeststub.EndpointChannelCreate)
+	if len(journal) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(journal))
+	}
+	assertRetryBackoff(t, journal, interval)
+	if minimum := 20*time.Millisecond + 2*interval; elapsed < minimum {
+		t.Fatalf("expected at least %v of latency and backoff, got %v", minimum, elapsed)
+	}
+	if elapsed > time.Second {
+		t.Fatalf("expected bounded retry latency, took %v", elapsed)
+	}
+}
+
+func TestDoWithRetryStopsOnCancelledBackoff(t *testing.T) {
+	stub := ingeststub.NewScenario(ingeststub.Options{}).
+		FailFirst(ingeststub.EndpointChannelCreate, 10, http.StatusServiceUnavailable).
+		Start()
+	t.Cleanup(stub.Close)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	err := doWithRetry(ctx, http.DefaultClient, http.MethodPost, stub.BaseURL()+"/v1/channels", []byte(`{}`), nil, nil, nil, 10, time.Second)
+	if err == nil {
+		t.Fatal("expected the cancelled context to end the retries")
+	}
+	if elapsed := time.Since(start); elapsed > 500*time.Millisecond {
+		t.Fatalf("expected cancellation to cut the backoff short, took %v", elapsed)
+	}
+	if attempts := len(stub.Journal(ingeststub.EndpointChannelCreate)); attempts != 1 {
+		t.Fatalf("expected a single attempt before cancellation, got %d", attempts)
+	}
+}
+
+func TestBootStreamSoakUnderInjectedFlakiness(t *testing.T) {
+	const (
+		boots      = 25
+		attempts   = 3
+		interval   = 2 * time.Millisecond
+		maxLatency = 4 * time.Millisecond
+	)
+	stub := ingeststub.NewScenario(ingeststub.Options{LiveJobIDs: []string{"job-soak"}}).
+		FailEvery(ingeststub.EndpointChannelCreate, 3, http.StatusInternalServerError).
+		Latency(ingeststub.EndpointChannelCreate, ingeststub.UniformLatency(0, maxLatency, 1)).
+		FailEvery(ingeststub.EndpointApplicationCreate, 4, http.StatusServiceUnavailable).
+		Latency(ingeststub.EndpointApplicationCreate, ingeststub.UniformLatency(time.Millisecond, maxLatency, 2)).
+		FailFirst(ingeststub.EndpointJobStart, attempts-1, http.StatusBadGateway).
+		Latency(ingeststub.EndpointJobStart, ingeststub.FixedLatency(time.Millisecond)).
+		Start()
+	t.Cleanup(stub.Close)
+	controller := faultTestController(stub, attempts, interval)
+
+	// Each boot makes three calls, each retried at most attempts-1 times.
+	perBoot := 3 * (attempts*maxLatency + (attempts-1)*interval)
+	budget := boots*perBoot + time.Second
+
+	start := time.Now()
+	for i := 0; i < boots; i++ {
+		params := BootParams{ChannelID: "channel-soak", StreamKey: "key", SessionID: "session-soak"}
+		result, err := controller.BootStream(context.Background(), params)
+		if err != nil {
+			t.Fatalf("boot %d: %v", i, err)
+		}
+		if len(result.JobIDs) != 1 {
+			t.Fatalf("boot %d: expected one job, got %v", i, result.JobIDs)
+		}
+	}
+	if elapsed := time.Since(start); elapsed > budget {
+		t.Fatalf("expected %d boots within %v, took %v", boots, budget, elapsed)
+	}
+
+	injected := 0
+	for _, endpoint := range []ingeststub.Endpoint{ingeststub.EndpointChannelCreate, ingeststub.EndpointApplicationCreate, ingeststub.EndpointJobStart} {
+		journal := stub.Journal(endpoint)
+		assertRetryBackoff(t, journal, interval)
+		for _, op := range journal {
+			if op.Injected {
+				injected++
+			}
+		}
+	}
+	if injected == 0 {
+		t.Fatal("expected the scenario to inject failures")
+	}
+	if deletes := len(stub.Journal(ingeststub.EndpointChannelDelete)); deletes != 0 {
+		t.Fatalf("expected no rollbacks when retries recover, got %d channel deletes", deletes)
+	}
+}
+
+func TestBootStreamWithLostJobsSurfacesInHealthChecks(t *testing.T) {
+	stub := ingeststub.NewScenario(ingeststub.Options{LiveJobIDs: []string{"job-lost"}}).LoseStartedJobs().Start()
+	t.Cleanup(stub.Close)
+	controller := faultTestController(stub, 2, time.Millisecond)
+
+	params := BootParams{ChannelID: "channel-lost", StreamKey: "key", SessionID: "session-lost"}
+	result, err := controller.BootStream(context.Background(), params)
+	if err != nil {
+		t.Fatalf("BootStream: %v", err)
+	}
+
+	for _, status := range controller.HealthChecks(context.Background()) {
+		if status.Status != "error" || !strings.Contains(status.Detail, "503") {
+			t.Fatalf("expected %s to report the missing job, got %+v", status.Component, status)
+		}
+	}
+
+	if err := controller.ShutdownStream(context.Background(), params.ChannelID, params.SessionID, result.JobIDs); err != nil {
+		t.Fatalf("ShutdownStream: %v", err)
+	}
+	if missing := stub.MissingJobs(); len(missing) != 0 {
+		t.Fatalf("expected stopping the session to clear missing jobs, got %v", missing)
+	}
+	for _, status := range controller.HealthChecks(context.Background()) {
+		if status.Status != "ok" {
+			t.Fatalf("expected %s healthy after shutdown, got %+v", status.Component, status)
+		}
+	}
+}
+
+func TestBootStreamCreatesChannelAndApplicationInParallel(t *testing.T) {
+	const latency = 150 * time.Millisecond
+	stub := ingeststub.NewScenario(ingeststub.Options{LiveJobIDs: []string{"job-parallel"}}).
+		Latency(ingeststub.EndpointChannelCreate, ingeststub.FixedLatency(latency)).
+		Latency(ingeststub.EndpointApplicationCreate, ingeststub.FixedLatency(latency)).
+		Start()
+	t.Cleanup(stub.Close)
+	controller := faultTestController(stub, 1, time.Millisecond)
+
+	start := time.Now()
+	if _, err := controller.BootStream(context.Background(), BootParams{ChannelID: "channel-parallel", StreamKey: "key", SessionID: "session-parallel"}); err != nil {
+		t.Fatalf("BootStream: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed >= 2*latency {
+		t.Fatalf("expected the channel and application to be created concurrently, took %v", elapsed)
+	}
+	jobs := stub.Journal(ingeststub.EndpointJobStart)
+	if len(jobs) != 1 {
+		t.Fatalf("expected one job start, got %d", len(jobs))
+	}
+	for _, endpoint := range []ingeststub.Endpoint{ingeststub.EndpointChannelCreate, ingeststub.EndpointApplicationCreate} {
+		created := stub.Journal(endpoint)
+		if len(created) != 1 || !jobs[0].Timestamp.After(created[0].Timestamp.Add(latency)) {
+			t.Fatalf("expected jobs to start after %s completed", endpoint)
+		}
+	}
+}
+
+func TestBootStreamStepTimeoutRollsBackOtherStep(t *testing.T) {
+	stub := ingeststub.NewScenario(ingeststub.Options{}).
+		Latency(ingeststub.EndpointApplicationCreate, ingeststub.FixedLatency(5*time.Second)).
+		Start()
+	t.Cleanup(stub.Close)
+	controller := faultTestController(stub, 3, time.Millisecond)
+	controller.config.BootStepTimeout = 50 * time.Millisecond
+
+	start := time.Now()
+	_, err := controller.BootStream(context.Background(), BootParams{ChannelID: "channel-slow", StreamKey: "key"})
+	var stepErr *BootStepError
+	if !errors.As(err, &stepErr) || stepErr.Step != BootStepApplication || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the application step to time out, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("expected the step deadline to cut the boot short, took %v", elapsed)
+	}
+	if deletes := stub.Journal(ingeststub.EndpointChannelDelete); len(deletes) != 1 || deletes[0].ChannelID != "channel-slow" {
+		t.Fatalf("expected the created channel to be rolled back, got %+v", deletes)
+	}
+	if starts := stub.Journal(ingeststub.EndpointJobStart); len(starts) != 0 {
+		t.Fatalf("expected no jobs without an application, got %d", len(starts))
+	}
+}
+
+func TestBootStreamDividesBudgetAcrossSteps(t *testing.T) {
+	stub := ingeststub.NewScenario(ingeststub.Options{}).
+		Latency(ingeststub.EndpointApplicationCreate, ingeststub.FixedLatency(200*time.Millisecond)).
+		Latency(ingeststub.EndpointJobStart, ingeststub.FixedLatency(5*time.Second)).
+		Start()
+	t.Cleanup(stub.Close)
+	controller := faultTestController(stub, 3, time.Millisecond)
+	controller.config.BootCleanupReserve = 100 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 900*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := controller.BootStream(ctx, BootParams{ChannelID: "channel-budget", StreamKey: "key"})
+	elapsed := time.Since(start)
+	var stepErr *BootStepError
+	if !errors.As(err, &stepErr) || stepErr.Step != BootStepJobs || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected the jobs step to run out of budget, got %v", err)
+	}
+	starts := stub.Journal(ingeststub.EndpointJobStart)
+	if len(starts) == 0 {
+		t.Fatal("expected the jobs step to be attempted")
+	}
+	// Provisioning finished well inside its even share of 400ms, so the jobs
+	// step inherits what it left unused rather than a fixed share.
+	if ran := start.Add(elapsed).Sub(starts[0].Timestamp); ran < 500*time.Millisecond {
+		t.Fatalf("expected the jobs step to get the unused budget, it ran for %v", ran)
+	}
+	if elapsed > time.Second {
+		t.Fatalf("expected the boot to finish by the caller's deadline, took %v", elapsed)
+	}
+	if deletes := stub.Journal(ingeststub.EndpointChannelDelete); len(deletes) != 1 {
+		t.Fatalf("expected the channel to be rolled back, got %+v", deletes)
+	}
+}
+
+func TestBootStreamStepShareCutsSlowStep(t *testing.T) {
+	stub := ingeststub.NewScenario(ingeststub.Options{}).
+		Latency(ingeststub.EndpointApplicationCreate, ingeststub.FixedLatency(5*time.Second)).
+		Start()
+	t.Cleanup(stub.Close)
+	controller := faultTestController(stub, 3, time.Millisecond)
+	controller.config.BootCleanupReserve = 100 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 900*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := controller.BootStream(ctx, BootParams{ChannelID: "channel-share", StreamKey: "key"})
+	elapsed := time.Since(start)
+	var stepErr *BootStepError
+	if !errors.As(err, &stepErr) || stepErr.Step != BootStepApplication {
+		t.Fatalf("expected the application step to fail, got %v", err)
+	}
+	// The application step may use half of the 800ms left after the reserve,
+	// leaving the other half for the jobs step.
+	if elapsed < 350*time.Millisecond || elapsed > 700*time.Millisecond {
+		t.Fatalf("expected the step to be cut at its 400ms share, took %v", elapsed)
+	}
+}
+
+func TestBootStreamRollbackRunsOnCleanupReserve(t *testing.T) {
+	stub := ingeststub.NewScenario(ingeststub.Options{}).
+		Latency(ingeststub.EndpointJobStart, ingeststub.FixedLatency(5*time.Second)).
+		Latency(ingeststub.EndpointApplicationDelete, ingeststub.FixedLatency(5*time.Second)).
+		Start()
+	t.Cleanup(stub.Close)
+	controller := faultTestController(stub, 3, time.Millisecond)
+	controller.config.BootCleanupReserve = 200 * time.Millisecond
+
+	ctx, cancel := context.WithTimeout(context.Background(), 400*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	_, err := controller.BootStream(ctx, BootParams{ChannelID: "channel-reserve", StreamKey: "key"})
+	elapsed := time.Since(start)
+	var stepErr *BootStepError
+	if !errors.As(err, &stepErr) || stepErr.Step != BootStepJobs {
+		t.Fatalf("expected the jobs step to fail, got %v", err)
+	}
+	// The hung application delete is cut at the end of the reserve, which
+	// ends with the caller's deadline.
+	if elapsed > 550*time.Millisecond {
+		t.Fatalf("expected rollback to stay within the reserved slice, took %v", elapsed)
+	}
+	if deletes := stub.Journal(ingeststub.EndpointChannelDelete); len(deletes) != 1 || deletes[0].ChannelID != "channel-reserve" {
+		t.Fatalf("expected the channel delete not to wait on the hung application delete, got %+v", deletes)
+	}
+}
+
+func TestBootStreamRollsBackSuccessfulHalf(t *testing.T) {
+	cases := []struct {
+		name       string
+		failed     ingeststub.Endpoint
+		step       string
+		rolledBack ingeststub.Endpoint
+		untouched  ingeststub.Endpoint
+	}{
+		{
+			name:       "channel fails",
+			failed:     ingeststub.EndpointChannelCreate,
+			step:       BootStepChannel,
+			rolledBack: ingeststub.EndpointApplicationDelete,
+			untouched:  ingeststub.EndpointChannelDelete,
+		},
+		{
+			name:       "application fails",
+			failed:     ingeststub.EndpointApplicationCreate,
+			step:       BootStepApplication,
+			rolledBack: ingeststub.EndpointChannelDelete,
+			untouched:  ingeststub.EndpointApplicationDelete,
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			stub := ingeststub.NewScenario(ingeststub.Options{}).
+				FailFirst(tc.failed, 10, http.StatusBadRequest).
+				Start()
+			t.Cleanup(stub.Close)
+			controller := faultTestController(stub, 2, time.Millisecond)
+
+			_, err := controller.BootStream(context.Background(), BootParams{ChannelID: "channel-half", StreamKey: "key"})
+			var stepErr *BootStepError
+			if !errors.As(err, &stepErr) || stepErr.Step != tc.step {
+				t.Fatalf("expected a %s step error, got %v", tc.step, err)
+			}
+			if deletes := stub.Journal(tc.rolledBack); len(deletes) != 1 || deletes[0].ChannelID != "channel-half" {
+				t.Fatalf("expected %s for rollback, got %+v", tc.rolledBack, deletes)
+			}
+			if deletes := stub.Journal(tc.untouched); len(deletes) != 0 {
+				t.Fatalf("did not expect %s, got %+v", tc.untouched, deletes)
+			}
+		})
+	}
+}
+
+func TestShutdownStreamToleratesPartialBoot(t *testing.T) {
+	stub := ingeststub.NewScenario(ingeststub.Options{}).
+		Script(ingeststub.EndpointApplicationDelete, http.StatusNotFound).
+		Script(ingeststub.EndpointJobStop, http.StatusNotFound).
+		Start()
+	t.Cleanup(stub.Close)
+	controller := faultTestController(stub, 2, time.Millisecond)
+
+	if err := controller.ShutdownStream(context.Background(), "channel-partial", "session-partial", []string{"", "job-gone"}); err != nil {
+		t.Fatalf("ShutdownStream: %v", err)
+	}
+	stops := stub.Journal(ingeststub.EndpointJobStop)
+	if len(stops) != 1 || stops[0].JobID != "job-gone" {
+		t.Fatalf("expected only the named job to be stopped, got %+v", stops)
+	}
+	if deletes := stub.Journal(ingeststub.EndpointChannelDelete); len(deletes) != 1 {
+		t.Fatalf("expected the channel to be deleted, got %+v", deletes)
+	}
+}
@@ -69,7 +69,16 @@ func TestHTTPControllerStreamLifecycleIntegration(t *testing.T) {
 	for _, op := range ops {
 		kinds = append(kinds, op.Kind)
 	}
+	if len(kinds) < 2 {
+		t.Fatalf("expected the boot operations to be recorded, got %v", kinds)
+	}
 
+	// The channel and application are created concurrently, so only the
+	// steps after them have a fixed order.
+	if created := []string{kinds[0], kinds[1]}; !contains(created, "channel-create") || !contains(created, "application-create") {
+		t.Fatalf("expected the channel and application to be created first, got %v", kinds)
+	}
+	kinds[0], kinds[1] = "channel-create", "application-create"
 	expectedOrder := []string{
 		"channel-create",
 		"application-create",
@@ -181,3 +190,64 @@ func filterOps(ops []ingeststub.Operation, kind string) []ingeststub.Operation {
 	}
 	return filtered
 }
+
+func TestHTTPControllerBootStreamNegotiatesPlaybackEndpoints(t *testing.T) {
+	boot := func(t *testing.T, opts ingeststub.Options) BootResult {
+		t.Helper()
+		stub := ingeststub.Start(opts)
+		t.Cleanup(stub.Close)
+		controller := HTTPController{
+			config: Config{
+				SRSBaseURL:        stub.BaseURL(),
+				OMEBaseURL:        stub.BaseURL(),
+				JobBaseURL:        stub.BaseURL(),
+				LadderProfiles:    []Rendition{{Name: "720p", Bitrate: 2400}},
+				HTTPRetryInterval: 5 * time.Millisecond,
+				HTTPMaxAttempts:   2,
+			},
+		}
+		result, err := controller.BootStream(context.Background(), BootParams{ChannelID: "channel-1", StreamKey: "key", SessionID: "session-1"})
+		if err != nil {
+			t.Fatalf("BootStream: %v", err)
+		}
+		return result
+	}
+
+	t.Run("multi-protocol response", func(t *testing.T) {
+		result := boot(t, ingeststub.Options{
+			PlaybackURL: "https://cdn.example.com/live/channel-1/playlist.m3u8",
+			PlaybackEndpoints: []map[string]interface{}{
+				{"protocol": "hls", "url": "https://cdn.example.com/live/channel-1/playlist.m3u8"},
+				{"protocol": "LLHLS", "url": "https://cdn.example.com/live/channel-1/llhls.m3u8", "priority": 2},
+				{"protocol": "webrtc", "url": "wss://cdn.example.com/live/channel-1", "priority": 1},
+				{"protocol": "srt", "url": "srt://cdn.example.com:9999"},
+				{"protocol": "hls", "url": ""},
+			},
+		})
+		want := []string{"webrtc wss://cdn.example.com/live/channel-1", "llhls https://cdn.example.com/live/channel-1/llhls.m3u8", "hls https://cdn.example.com/live/channel-1/playlist.m3u8"}
+		if len(result.PlaybackEndpoints) != len(want) {
+			t.Fatalf("expected %d endpoints, got %+v", len(want), result.PlaybackEndpoints)
+		}
+		for i, endpoint := range result.PlaybackEndpoints {
+			if got := endpoint.Protocol + " " + endpoint.URL; got != want[i] {
+				t.Fatalf("endpoint %d: want %q, got %q", i, want[i], got)
+			}
+			if endpoint.Priority <= 0 {
+				t.Fatalf("endpoint %d has no priority: %+v", i, endpoint)
+			}
+		}
+		if result.PlaybackURL != "https://cdn.example.com/live/channel-1/llhls.m3u8" {
+			t.Fatalf("expected the legacy URL to be the preferred HLS endpoint, got %q", result.PlaybackURL)
+		}
+	})
+
+	t.Run("legacy response", func(t *testing.T) {
+		result := boot(t, ingeststub.Options{PlaybackURL: "https://playback/live/channel-1/playlist.m3u8"})
+		if result.PlaybackURL != "https://playback/live/channel-1/playlist.m3u8" {
+			t.Fatalf("unexpected playback URL %q", result.PlaybackURL)
+		}
+		if len(result.PlaybackEndpoints) != 1 || result.PlaybackEndpoints[0].Protocol != "hls" || result.PlaybackEndpoints[0].URL != result.PlaybackURL {
+			t.Fatalf("expected a single HLS endpoint, got %+v", result.PlaybackEndpoints)
+		}
+	})
+}
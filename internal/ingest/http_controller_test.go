@@ -10,6 +10,7 @@ import (
 	"testing"
 	"time"
 
+	"bitriver-live/internal/models"
 	"bitriver-live/internal/observability/metrics"
 )
 
@@ -132,13 +133,13 @@ type fakeApplicationAdapter struct {
 	lastDeleteChannelID  string
 }
 
-func (f *fakeApplicationAdapter) CreateApplication(ctx context.Context, channelID string, renditions []string) (string, string, error) {
+func (f *fakeApplicationAdapter) CreateApplication(ctx context.Context, channelID string, renditions []string) (applicationResult, error) {
 	f.lastCreateChannelID = channelID
 	f.lastCreateRenditions = append([]string{}, renditions...)
 	if f.createErr != nil {
-		return "", "", f.createErr
+		return applicationResult{}, f.createErr
 	}
-	return f.origin, f.playback, nil
+	return applicationResult{OriginURL: f.origin, PlaybackURL: f.playback}, nil
 }
 
 func (f *fakeApplicationAdapter) DeleteApplication(ctx context.Context, channelID string) error {
@@ -156,15 +157,33 @@ type fakeTranscoderAdapter struct {
 	lastStartChannelID string
 	lastStartSessionID string
 	lastStartOriginURL string
+	lastStartLimits    models.TranscodeLimits
+
+	stopJobIDs   []string
+	stopJobBytes map[string]int64
 
-	stopJobIDs []string
+	uploadStatus    UploadOutput
+	uploadStatusErr error
 
 	lastUploadReq uploadJobRequest
 	uploadResult  uploadJobResult
+
+	frame    Frame
+	frameErr error
+
+	clip    LiveClip
+	clipErr error
+
+	restreamErr         error
+	stopRestreamErr     error
+	restreamTargets     []RestreamTarget
+	restreamOriginURL   string
+	stopRestreamChannel string
 }
 
-func (f *fakeTranscoderAdapter) StartJobs(ctx context.Context, channelID, sessionID, originURL string, ladder []Rendition) ([]string, []Rendition, error) {
+func (f *fakeTranscoderAdapter) StartJobs(ctx context.Context, channelID, sessionID, originURL string, ladder []Rendition, limits models.TranscodeLimits) ([]string, []Rendition, error) {
 	f.lastStartChannelID = channelID
+	f.lastStartLimits = limits
 	f.lastStartSessionID = sessionID
 	f.lastStartOriginURL = originURL
 	f.startJobRenditions = CloneRenditions(ladder)
@@ -174,9 +193,16 @@ func (f *fakeTranscoderAdapter) StartJobs(ctx context.Context, channelID, sessio
 	return append([]string{}, f.startJobIDs...), CloneRenditions(f.startJobRenditions), nil
 }
 
-func (f *fakeTranscoderAdapter) StopJob(ctx context.Context, jobID string) error {
+func (f *fakeTranscoderAdapter) StopJob(ctx context.Context, jobID string) (int64, error) {
 	f.stopJobIDs = append(f.stopJobIDs, jobID)
-	return f.stopJobErr
+	if f.stopJobErr != nil {
+		return 0, f.stopJobErr
+	}
+	return f.stopJobBytes[jobID], nil
+}
+
+func (f *fakeTranscoderAdapter) UploadStatus(ctx context.Context, jobID string) (UploadOutput, error) {
+	return f.uploadStatus, f.uploadStatusErr
 }
 
 func (f *fakeTranscoderAdapter) StartUpload(ctx context.Context, req uploadJobRequest) (uploadJobResult, error) {
@@ -187,6 +213,39 @@ func (f *fakeTranscoderAdapter) StartUpload(ctx context.Context, req uploadJobRe
 	return f.uploadResult, nil
 }
 
+func (f *fakeTranscoderAdapter) FetchFrame(ctx context.Context, jobID string) (Frame, error) {
+	if f.frameErr != nil {
+		return Frame{}, f.frameErr
+	}
+	return f.frame, nil
+}
+
+func (f *fakeTranscoderAdapter) CreateClip(ctx context.Context, jobID string, durationSeconds int) (LiveClip, error) {
+	if f.clipErr != nil {
+		return LiveClip{}, f.clipErr
+	}
+	return f.clip, nil
+}
+
+func (f *fakeTranscoderAdapter) StartRestreams(ctx context.Context, channelID, sessionID, originURL string, targets []RestreamTarget) error {
+	f.restreamOriginURL = originURL
+	f.restreamTargets = append([]RestreamTarget{}, targets...)
+	return f.restreamErr
+}
+
+func (f *fakeTranscoderAdapter) StopRestreams(ctx context.Context, channelID string) error {
+	f.stopRestreamChannel = channelID
+	return f.stopRestreamErr
+}
+
+func (f *fakeTranscoderAdapter) RestreamStatus(ctx context.Context, channelID string) ([]RestreamStatus, error) {
+	statuses := make([]RestreamStatus, 0, len(f.restreamTargets))
+	for _, target := range f.restreamTargets {
+		statuses = append(statuses, RestreamStatus{TargetID: target.ID, State: RestreamConnected})
+	}
+	return statuses, nil
+}
+
 // ---- BootStream tests ----
 
 // TestHTTPControllerBootStreamSuccess verifies the happy path for BootStream:
@@ -254,9 +313,76 @@ func TestHTTPControllerBootStreamSuccess(t *testing.T) {
 	}
 }
 
+func TestLivePreviewURL(t *testing.T) {
+	cases := []struct {
+		name       string
+		jobIDs     []string
+		renditions []Rendition
+		want       string
+	}{
+		{
+			name:       "mirrored manifest",
+			jobIDs:     []string{"job-1"},
+			renditions: []Rendition{{Name: "480p", ManifestURL: "https://cdn.example.com/live/job-1/480p/index.m3u8"}},
+			want:       "https://cdn.example.com/live/job-1/preview.jpg",
+		},
+		{
+			name:       "second job owns the manifests",
+			jobIDs:     []string{"job-0", "job-2"},
+			renditions: []Rendition{{Name: "720p", ManifestURL: "https://cdn.example.com/base/live/job-2/720p/index.m3u8"}},
+			want:       "https://cdn.example.com/base/live/job-2/preview.jpg",
+		},
+		{
+			name:       "local manifests",
+			jobIDs:     []string{"job-1"},
+			renditions: []Rendition{{Name: "720p", ManifestURL: "720p/index.m3u8"}},
+		},
+		{
+			name:       "other job",
+			jobIDs:     []string{"job-1"},
+			renditions: []Rendition{{Name: "720p", ManifestURL: "https://cdn.example.com/live/job-10/720p/index.m3u8"}},
+		},
+		{name: "no jobs", renditions: []Rendition{{ManifestURL: "https://cdn.example.com/live/job-1/index.m3u8"}}},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			if got := LivePreviewURL(tc.jobIDs, tc.renditions); got != tc.want {
+				t.Fatalf("expected %q, got %q", tc.want, got)
+			}
+		})
+	}
+}
+
 // TestHTTPControllerBootStreamRollsBackOnAppFailure verifies that if the
 // OME application creation fails, the previously created SRS channel is
 // deleted as part of rollback.
+func TestHTTPControllerBootStreamAppliesChannelLimitOverrides(t *testing.T) {
+	tr := &fakeTranscoderAdapter{startJobIDs: []string{"job-1"}}
+	controller := HTTPController{
+		config: Config{
+			LadderProfiles:  []Rendition{{Name: "720p", Bitrate: 2500}},
+			TranscodeLimits: models.TranscodeLimits{MaxHeight: 720, MaxBitrate: 4000, MaxRenditions: 2},
+		},
+		channels:     &fakeChannelAdapter{},
+		applications: &fakeApplicationAdapter{},
+		transcoder:   tr,
+	}
+
+	if _, err := controller.BootStream(context.Background(), BootParams{
+		ChannelID:       "channel-123",
+		StreamKey:       "stream-key",
+		SessionID:       "session-abc",
+		TranscodeLimits: &models.TranscodeLimits{MaxHeight: 1080, MaxBitrate: 9000},
+	}); err != nil {
+		t.Fatalf("BootStream: %v", err)
+	}
+
+	want := models.TranscodeLimits{MaxHeight: 1080, MaxBitrate: 9000, MaxRenditions: 2}
+	if tr.lastStartLimits != want {
+		t.Fatalf("expected limits %+v, got %+v", want, tr.lastStartLimits)
+	}
+}
+
 func TestHTTPControllerBootStreamRollsBackOnAppFailure(t *testing.T) {
 	ch := &fakeChannelAdapter{
 		createPrimary: "rtmp://primary",
@@ -332,6 +458,47 @@ func TestHTTPControllerBootStreamRollsBackOnTranscoderFailure(t *testing.T) {
 	}
 }
 
+// TestHTTPControllerRestreamFailuresDoNotAffectPrimary verifies that
+// restreams are pushed from the origin, and that failing to start or stop
+// them leaves the primary pipeline alone.
+func TestHTTPControllerRestreamFailuresDoNotAffectPrimary(t *testing.T) {
+	app := &fakeApplicationAdapter{origin: "http://origin", playback: "https://playback"}
+	tr := &fakeTranscoderAdapter{
+		startJobIDs:     []string{"job-1"},
+		restreamErr:     errors.New("restreams unavailable"),
+		stopRestreamErr: errors.New("restreams unavailable"),
+	}
+	controller := HTTPController{
+		channels:     &fakeChannelAdapter{},
+		applications: app,
+		transcoder:   tr,
+	}
+
+	targets := []RestreamTarget{{ID: "target-1", URL: "rtmp://live.example.com/app", StreamKey: "secret"}}
+	result, err := controller.BootStream(context.Background(), BootParams{
+		ChannelID: "channel-123",
+		StreamKey: "stream-key",
+		SessionID: "session-abc",
+		Restreams: targets,
+	})
+	if err != nil {
+		t.Fatalf("BootStream: %v", err)
+	}
+	if len(result.JobIDs) != 1 || app.lastDeleteChannelID != "" {
+		t.Fatalf("expected the primary pipeline kept, got %+v", result)
+	}
+	if tr.restreamOriginURL != "http://origin" || len(tr.restreamTargets) != 1 || tr.restreamTargets[0] != targets[0] {
+		t.Fatalf("expected restreams pushed from the origin, got %s %+v", tr.restreamOriginURL, tr.restreamTargets)
+	}
+
+	if err := controller.ShutdownStream(context.Background(), "channel-123", "session-abc", result.JobIDs); err != nil {
+		t.Fatalf("expected restream teardown failures to be logged only, got %v", err)
+	}
+	if tr.stopRestreamChannel != "channel-123" || len(tr.stopJobIDs) != 1 {
+		t.Fatalf("expected restreams and jobs stopped, got %q and %v", tr.stopRestreamChannel, tr.stopJobIDs)
+	}
+}
+
 func TestHTTPControllerBootStreamMetricsRecorded(t *testing.T) {
 	metrics.Default().Reset()
 	t.Cleanup(metrics.Default().Reset)
@@ -410,6 +577,28 @@ func TestHTTPControllerShutdownStreamAggregatesErrors(t *testing.T) {
 	}
 }
 
+// TestHTTPControllerShutdownStreamOutputSumsJobs verifies that the output
+// sizes reported for each stopped job are added up.
+func TestHTTPControllerShutdownStreamOutputSumsJobs(t *testing.T) {
+	tr := &fakeTranscoderAdapter{stopJobBytes: map[string]int64{"job-1": 1500, "job-2": 2500}}
+	controller := HTTPController{
+		channels:     &fakeChannelAdapter{},
+		applications: &fakeApplicationAdapter{},
+		transcoder:   tr,
+	}
+
+	size, err := ShutdownStreamWithOutput(context.Background(), &controller, "channel-123", "session-abc", []string{"job-1", "", "job-2"})
+	if err != nil {
+		t.Fatalf("ShutdownStreamWithOutput: %v", err)
+	}
+	if size != 4000 {
+		t.Fatalf("expected 4000 output bytes, got %d", size)
+	}
+	if size, err := ShutdownStreamWithOutput(context.Background(), NoopController{}, "channel-123", "session-abc", nil); err != nil || size != 0 {
+		t.Fatalf("expected controllers without output reporting to report zero, got %d, %v", size, err)
+	}
+}
+
 func TestHTTPControllerShutdownMetricsRecorded(t *testing.T) {
 	metrics.Default().Reset()
 	t.Cleanup(metrics.Default().Reset)
@@ -0,0 +1,213 @@
+package ingest
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"bitriver-live/internal/models"
+)
+
+// LadderSelection is a rendition ladder fitted to an upload's source.
+type LadderSelection struct {
+	// Renditions are the rungs to transcode, in ladder order.
+	Renditions []Rendition
+	// Skipped names the rungs dropped because they exceed the source.
+	Skipped []string
+	// Passthrough reports that no rung fit, so Renditions holds a single
+	// rendition at the source's own size.
+	Passthrough bool
+}
+
+var renditionHeightPattern = regexp.MustCompile(`(?i)(\d{3,4})p`)
+
+// RenditionDimensions parses a rendition name such as "720p" (assumed 16:9)
+// or "1280x720" into its frame size.
+func RenditionDimensions(name string) (width, height int, ok bool) {
+	trimmed := strings.TrimSpace(name)
+	if w, h, found := strings.Cut(strings.ToLower(trimmed), "x"); found {
+		width, errW := strconv.Atoi(strings.TrimSpace(w))
+		height, errH := strconv.Atoi(strings.TrimSpace(h))
+		if errW == nil && errH == nil && width > 0 && height > 0 {
+			return width, height, true
+		}
+	}
+	if match := renditionHeightPattern.FindStringSubmatch(trimmed); len(match) == 2 {
+		if height, err := strconv.Atoi(match[1]); err == nil && height > 0 {
+			return (height*16/9 + 1) &^ 1, height, true
+		}
+	}
+	return 0, 0, false
+}
+
+// SelectUploadLadder drops the rungs of ladder that would upscale source.
+// Rungs are compared by their shorter side against the source's shorter
+// side, so vertical video keeps the rungs matching its width. When every
+// rung is larger than the source, a single passthrough rendition at the
+// source size (rounded down to even dimensions) replaces the ladder, capped
+// at the smallest rung's bitrate and the source's own bitrate. Rungs with
+// unrecognised names are kept, and an unprobed source keeps the full ladder.
+func SelectUploadLadder(ladder []Rendition, source MediaInfo) LadderSelection {
+	selection := LadderSelection{}
+	if source.Width <= 0 || source.Height <= 0 {
+		selection.Renditions = CloneRenditions(ladder)
+		return selection
+	}
+	sourceShort := min(source.Width, source.Height)
+
+	lowestBitrate := 0
+	for _, rung := range ladder {
+		if rung.Bitrate > 0 && (lowestBitrate == 0 || rung.Bitrate < lowestBitrate) {
+			lowestBitrate = rung.Bitrate
+		}
+		width, height, ok := RenditionDimensions(rung.Name)
+		if ok && min(width, height) > sourceShort {
+			selection.Skipped = append(selection.Skipped, rung.Name)
+			continue
+		}
+		selection.Renditions = append(selection.Renditions, rung)
+	}
+	if len(selection.Renditions) > 0 || len(ladder) == 0 {
+		return selection
+	}
+
+	bitrate := lowestBitrate
+	if source.Bitrate > 0 && (bitrate == 0 || source.Bitrate < bitrate) {
+		bitrate = source.Bitrate
+	}
+	selection.Renditions = []Rendition{{
+		Name:    fmt.Sprintf("%dx%d", evenFloor(source.Width), evenFloor(source.Height)),
+		Bitrate: bitrate,
+	}}
+	selection.Passthrough = true
+	return selection
+}
+
+func evenFloor(value int) int {
+	if value < 2 {
+		return 2
+	}
+	return value &^ 1
+}
+
+// CheckLadder reports the first way ladder breaks limits as a
+// *TranscodeLimitError. MaxHeight is compared with each rung's shorter side,
+// so portrait video is held to the same cap as its landscape equivalent.
+// Rungs whose names carry no resolution are not height checked, and rungs
+// without a bitrate count as zero towards MaxBitrate.
+func CheckLadder(ladder []Rendition, limits models.TranscodeLimits) error {
+	if limits.MaxRenditions > 0 && len(ladder) > limits.MaxRenditions {
+		return renditionsExceeded(len(ladder), limits.MaxRenditions)
+	}
+	if limits.MaxHeight > 0 {
+		for _, rung := range ladder {
+			if height, ok := renditionShortSide(rung.Name); ok && height > limits.MaxHeight {
+				return resolutionExceeded(rung.Name, height, limits.MaxHeight)
+			}
+		}
+	}
+	if limits.MaxBitrate > 0 {
+		if total := ladderBitrate(ladder); total > limits.MaxBitrate {
+			return bitrateExceeded(total, limits.MaxBitrate)
+		}
+	}
+	return nil
+}
+
+// ClampLadder fits ladder inside limits instead of rejecting it. Rungs taller
+// than MaxHeight are dropped, then the highest-bitrate rungs are dropped
+// until the rendition count and summed bitrate fit. The remaining rungs keep
+// their ladder order. A *TranscodeLimitError is returned when no rung fits.
+func ClampLadder(ladder []Rendition, limits models.TranscodeLimits) ([]Rendition, error) {
+	kept := make([]Rendition, 0, len(ladder))
+	var tallest Rendition
+	tallestHeight := 0
+	for _, rung := range ladder {
+		if height, ok := renditionShortSide(rung.Name); ok && limits.MaxHeight > 0 && height > limits.MaxHeight {
+			if height > tallestHeight {
+				tallest, tallestHeight = rung, height
+			}
+			continue
+		}
+		kept = append(kept, rung)
+	}
+	if len(kept) == 0 && len(ladder) > 0 {
+		return nil, resolutionExceeded(tallest.Name, tallestHeight, limits.MaxHeight)
+	}
+
+	order := make([]int, len(kept))
+	for i := range order {
+		order[i] = i
+	}
+	sort.SliceStable(order, func(a, b int) bool { return kept[order[a]].Bitrate > kept[order[b]].Bitrate })
+	dropped := make(map[int]bool)
+	total, count := ladderBitrate(kept), len(kept)
+	for _, idx := range order {
+		overCount := limits.MaxRenditions > 0 && count > limits.MaxRenditions
+		overBitrate := limits.MaxBitrate > 0 && total > limits.MaxBitrate
+		if !overCount && !overBitrate {
+			break
+		}
+		if count == 1 {
+			return nil, bitrateExceeded(total, limits.MaxBitrate)
+		}
+		dropped[idx] = true
+		total -= max(kept[idx].Bitrate, 0)
+		count--
+	}
+
+	clamped := make([]Rendition, 0, count)
+	for idx, rung := range kept {
+		if !dropped[idx] {
+			clamped = append(clamped, rung)
+		}
+	}
+	return clamped, nil
+}
+
+func renditionShortSide(name string) (int, bool) {
+	width, height, ok := RenditionDimensions(name)
+	if !ok {
+		return 0, false
+	}
+	return min(width, height), true
+}
+
+func ladderBitrate(ladder []Rendition) int {
+	total := 0
+	for _, rung := range ladder {
+		if rung.Bitrate > 0 {
+			total += rung.Bitrate
+		}
+	}
+	return total
+}
+
+func renditionsExceeded(value, limit int) *TranscodeLimitError {
+	return &TranscodeLimitError{
+		Reason:  LimitReasonRenditions,
+		Message: fmt.Sprintf("%d renditions requested; the maximum is %d", value, limit),
+		Limit:   limit,
+		Value:   value,
+	}
+}
+
+func resolutionExceeded(name string, height, limit int) *TranscodeLimitError {
+	return &TranscodeLimitError{
+		Reason:  LimitReasonResolution,
+		Message: fmt.Sprintf("rendition %s is %dp; the maximum is %dp", name, height, limit),
+		Limit:   limit,
+		Value:   height,
+	}
+}
+
+func bitrateExceeded(total, limit int) *TranscodeLimitError {
+	return &TranscodeLimitError{
+		Reason:  LimitReasonBitrate,
+		Message: fmt.Sprintf("ladder bitrate %d kbps exceeds the maximum of %d kbps", total, limit),
+		Limit:   limit,
+		Value:   total,
+	}
+}
@@ -0,0 +1,211 @@
+package ingest
+
+import (
+	"errors"
+	"reflect"
+	"testing"
+
+	"bitriver-live/internal/models"
+)
+
+func TestRenditionDimensions(t *testing.T) {
+	cases := []struct {
+		name          string
+		width, height int
+		ok            bool
+	}{
+		{name: "1080p", width: 1920, height: 1080, ok: true},
+		{name: "480p", width: 854, height: 480, ok: true},
+		{name: "1280x720", width: 1280, height: 720, ok: true},
+		{name: " 720X1280 ", width: 720, height: 1280, ok: true},
+		{name: "source"},
+		{name: ""},
+	}
+	for _, tc := range cases {
+		width, height, ok := RenditionDimensions(tc.name)
+		if width != tc.width || height != tc.height || ok != tc.ok {
+			t.Fatalf("RenditionDimensions(%q) = %d, %d, %v; want %d, %d, %v", tc.name, width, height, ok, tc.width, tc.height, tc.ok)
+		}
+	}
+}
+
+func TestSelectUploadLadder(t *testing.T) {
+	ladder := []Rendition{
+		{Name: "1080p", Bitrate: 6000},
+		{Name: "720p", Bitrate: 3000},
+		{Name: "480p", Bitrate: 1500},
+	}
+	cases := []struct {
+		name        string
+		ladder      []Rendition
+		source      MediaInfo
+		renditions  []Rendition
+		skipped     []string
+		passthrough bool
+	}{
+		{
+			name:       "unprobed source keeps ladder",
+			ladder:     ladder,
+			renditions: ladder,
+		},
+		{
+			name:       "4k source keeps ladder",
+			ladder:     ladder,
+			source:     MediaInfo{Width: 3840, Height: 2160},
+			renditions: ladder,
+		},
+		{
+			name:       "exact 720p source",
+			ladder:     ladder,
+			source:     MediaInfo{Width: 1280, Height: 720},
+			renditions: ladder[1:],
+			skipped:    []string{"1080p"},
+		},
+		{
+			name:       "between rungs keeps closest below",
+			ladder:     ladder,
+			source:     MediaInfo{Width: 1024, Height: 576},
+			renditions: ladder[2:],
+			skipped:    []string{"1080p", "720p"},
+		},
+		{
+			name:       "vertical video selects by width",
+			ladder:     ladder,
+			source:     MediaInfo{Width: 720, Height: 1280},
+			renditions: ladder[1:],
+			skipped:    []string{"1080p"},
+		},
+		{
+			name:        "small source passes through at ladder floor bitrate",
+			ladder:      ladder,
+			source:      MediaInfo{Width: 640, Height: 360},
+			renditions:  []Rendition{{Name: "640x360", Bitrate: 1500}},
+			skipped:     []string{"1080p", "720p", "480p"},
+			passthrough: true,
+		},
+		{
+			name:        "odd small source rounds down to even and keeps source bitrate",
+			ladder:      ladder,
+			source:      MediaInfo{Width: 425, Height: 239, Bitrate: 800},
+			renditions:  []Rendition{{Name: "424x238", Bitrate: 800}},
+			skipped:     []string{"1080p", "720p", "480p"},
+			passthrough: true,
+		},
+		{
+			name:        "small vertical source passes through upright",
+			ladder:      ladder,
+			source:      MediaInfo{Width: 361, Height: 641},
+			renditions:  []Rendition{{Name: "360x640", Bitrate: 1500}},
+			skipped:     []string{"1080p", "720p", "480p"},
+			passthrough: true,
+		},
+		{
+			name:       "unknown rung names are kept",
+			ladder:     []Rendition{{Name: "1080p"}, {Name: "archive"}},
+			source:     MediaInfo{Width: 640, Height: 360},
+			renditions: []Rendition{{Name: "archive"}},
+			skipped:    []string{"1080p"},
+		},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			selection := SelectUploadLadder(tc.ladder, tc.source)
+			if !reflect.DeepEqual(selection.Renditions, tc.renditions) {
+				t.Fatalf("renditions = %+v, want %+v", selection.Renditions, tc.renditions)
+			}
+			if !reflect.DeepEqual(selection.Skipped, tc.skipped) {
+				t.Fatalf("skipped = %v, want %v", selection.Skipped, tc.skipped)
+			}
+			if selection.Passthrough != tc.passthrough {
+				t.Fatalf("passthrough = %v, want %v", selection.Passthrough, tc.passthrough)
+			}
+			for _, rendition := range selection.Renditions {
+				if width, height, ok := RenditionDimensions(rendition.Name); ok && (width%2 != 0 || height%2 != 0) {
+					t.Fatalf("rendition %s has odd dimensions", rendition.Name)
+				}
+			}
+		})
+	}
+}
+
+func TestCheckLadder(t *testing.T) {
+	ladder := []Rendition{
+		{Name: "1080p", Bitrate: 6000},
+		{Name: "720p", Bitrate: 3000},
+		{Name: "480p", Bitrate: 1500},
+	}
+	cases := []struct {
+		name   string
+		limits models.TranscodeLimits
+		reason string
+		limit  int
+		value  int
+	}{
+		{name: "no limits"},
+		{name: "within limits", limits: models.TranscodeLimits{MaxHeight: 1080, MaxBitrate: 10500, MaxRenditions: 3}},
+		{name: "too many renditions", limits: models.TranscodeLimits{MaxRenditions: 2}, reason: LimitReasonRenditions, limit: 2, value: 3},
+		{name: "too tall", limits: models.TranscodeLimits{MaxHeight: 720}, reason: LimitReasonResolution, limit: 720, value: 1080},
+		{name: "too much bitrate", limits: models.TranscodeLimits{MaxBitrate: 8000}, reason: LimitReasonBitrate, limit: 8000, value: 10500},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			err := CheckLadder(ladder, tc.limits)
+			if tc.reason == "" {
+				if err != nil {
+					t.Fatalf("CheckLadder: %v", err)
+				}
+				return
+			}
+			var limitErr *TranscodeLimitError
+			if !errors.As(err, &limitErr) {
+				t.Fatalf("expected TranscodeLimitError, got %v", err)
+			}
+			if limitErr.Reason != tc.reason || limitErr.Limit != tc.limit || limitErr.Value != tc.value {
+				t.Fatalf("unexpected rejection: %+v", limitErr)
+			}
+		})
+	}
+
+	if err := CheckLadder([]Rendition{{Name: "720x1280"}}, models.TranscodeLimits{MaxHeight: 720}); err != nil {
+		t.Fatalf("expected portrait 720p to fit a 720 cap, got %v", err)
+	}
+}
+
+func TestClampLadder(t *testing.T) {
+	ladder := []Rendition{
+		{Name: "1080p", Bitrate: 6000},
+		{Name: "720p", Bitrate: 3000},
+		{Name: "480p", Bitrate: 1500},
+	}
+	cases := []struct {
+		name       string
+		limits     models.TranscodeLimits
+		renditions []Rendition
+		reason     string
+	}{
+		{name: "no limits keeps ladder", renditions: ladder},
+		{name: "height drops tall rungs", limits: models.TranscodeLimits{MaxHeight: 720}, renditions: ladder[1:]},
+		{name: "count drops most expensive", limits: models.TranscodeLimits{MaxRenditions: 2}, renditions: ladder[1:]},
+		{name: "bitrate drops until it fits", limits: models.TranscodeLimits{MaxBitrate: 2000}, renditions: ladder[2:]},
+		{name: "bitrate below cheapest rung rejects", limits: models.TranscodeLimits{MaxBitrate: 1000}, reason: LimitReasonBitrate},
+		{name: "height below every rung rejects", limits: models.TranscodeLimits{MaxHeight: 360}, reason: LimitReasonResolution},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			got, err := ClampLadder(ladder, tc.limits)
+			if tc.reason != "" {
+				var limitErr *TranscodeLimitError
+				if !errors.As(err, &limitErr) || limitErr.Reason != tc.reason {
+					t.Fatalf("expected %s rejection, got %v", tc.reason, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ClampLadder: %v", err)
+			}
+			if !reflect.DeepEqual(got, tc.renditions) {
+				t.Fatalf("ClampLadder = %+v, want %+v", got, tc.renditions)
+			}
+		})
+	}
+}
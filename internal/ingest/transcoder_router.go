@@ -0,0 +1,370 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"math/rand"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"bitriver-live/internal/models"
+)
+
+// defaultTranscoderBackend names the backend built from Config.JobBaseURL
+// when Config.Transcoders is empty.
+const defaultTranscoderBackend = "default"
+
+// ErrTranscoderBackendUnknown reports that a job handle names a transcoder
+// backend that is no longer configured, so the job cannot be reached.
+var ErrTranscoderBackendUnknown = errors.New("transcoder backend not configured")
+
+// backendState is what the pool knows about one backend's health and load.
+type backendState struct {
+	// healthy is false from a failed health probe until one succeeds.
+	healthy bool
+	// runningJobs is the job count the backend last reported, or -1 when
+	// its health endpoint has not reported one.
+	runningJobs int
+	// placed counts the jobs placed on the backend since it last reported
+	// its load.
+	placed int
+}
+
+// transcoderPool decides which backend new jobs are placed on. Health probes
+// feed it each backend's health and load while placements read them, so it
+// is safe for concurrent use.
+type transcoderPool struct {
+	backends []TranscoderBackend
+	// intn returns a random number in [0, n) for weighted placement. Tests
+	// substitute a deterministic sequence.
+	intn func(n int) int
+
+	mu    sync.Mutex
+	state []backendState
+}
+
+func newTranscoderPool(backends []TranscoderBackend) *transcoderPool {
+	state := make([]backendState, len(backends))
+	for i := range state {
+		state[i] = backendState{healthy: true, runningJobs: -1}
+	}
+	return &transcoderPool{backends: backends, intn: rand.Intn, state: state}
+}
+
+// place picks the backend for a new job and counts the job against it.
+// Backends that failed their last health probe are skipped unless every
+// backend did. When every candidate has reported its load the least loaded
+// one relative to its weight wins; otherwise the choice is weighted random.
+func (p *transcoderPool) place() int {
+	if len(p.backends) == 1 {
+		return 0
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	candidates := make([]int, 0, len(p.backends))
+	for i, state := range p.state {
+		if state.healthy {
+			candidates = append(candidates, i)
+		}
+	}
+	if len(candidates) == 0 {
+		for i := range p.backends {
+			candidates = append(candidates, i)
+		}
+	}
+	chosen, ok := p.leastLoaded(candidates)
+	if !ok {
+		chosen = p.weightedRandom(candidates)
+	}
+	p.state[chosen].placed++
+	return chosen
+}
+
+func (p *transcoderPool) leastLoaded(candidates []int) (int, bool) {
+	best := -1
+	for _, i := range candidates {
+		if p.state[i].runningJobs < 0 {
+			return 0, false
+		}
+		if best < 0 {
+			best = i
+			continue
+		}
+		// Compare load/weight without dividing.
+		if p.load(i)*p.backends[best].Weight < p.load(best)*p.backends[i].Weight {
+			best = i
+		}
+	}
+	return best, best >= 0
+}
+
+func (p *transcoderPool) load(i int) int {
+	return p.state[i].runningJobs + p.state[i].placed
+}
+
+func (p *transcoderPool) weightedRandom(candidates []int) int {
+	total := 0
+	for _, i := range candidates {
+		total += p.backends[i].Weight
+	}
+	n := p.intn(total)
+	for _, i := range candidates {
+		n -= p.backends[i].Weight
+		if n < 0 {
+			return i
+		}
+	}
+	return candidates[len(candidates)-1]
+}
+
+// observe records the outcome of a health probe of backend i. runningJobs is
+// -1 when the probe did not report a load.
+func (p *transcoderPool) observe(i int, healthy bool, runningJobs int) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.state[i] = backendState{healthy: healthy, runningJobs: runningJobs}
+}
+
+// healthy reports whether backend i passed its last health probe.
+func (p *transcoderPool) healthy(i int) bool {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return p.state[i].healthy
+}
+
+// component is the health component name of backend i: "transcoder" when it
+// is the only backend, otherwise "transcoder:<name>".
+func (p *transcoderPool) component(i int) string {
+	if len(p.backends) == 1 {
+		return "transcoder"
+	}
+	return "transcoder:" + p.backends[i].Name
+}
+
+// handle returns the job handle for jobID on backend i. With a single
+// backend it is the job ID itself, so handles recorded before more backends
+// were added keep working.
+func (p *transcoderPool) handle(i int, jobID string) string {
+	if len(p.backends) == 1 || jobID == "" {
+		return jobID
+	}
+	return p.backends[i].Name + ":" + jobID
+}
+
+// resolve splits a handle into its backend and the backend's job ID. Handles
+// without a backend name, from before more backends were added, belong to
+// the first backend.
+func (p *transcoderPool) resolve(handle string) (int, string, error) {
+	if len(p.backends) == 1 {
+		return 0, handle, nil
+	}
+	name, jobID, ok := strings.Cut(handle, ":")
+	if !ok {
+		return 0, handle, nil
+	}
+	for i, backend := range p.backends {
+		if backend.Name == name {
+			return i, jobID, nil
+		}
+	}
+	return 0, "", fmt.Errorf("job %s: %w: %s", handle, ErrTranscoderBackendUnknown, name)
+}
+
+// transcoderRouter implements transcoderAdapter over the backends of a pool.
+// Jobs and uploads are placed when they start, and the backend is recorded
+// in the handle returned for them, "<backend>:<job ID>", so stops, status
+// polls, frames, and clips reach the process that owns the job, even after
+// the API restarts.
+type transcoderRouter struct {
+	pool     *transcoderPool
+	adapters []transcoderAdapter
+	logger   *slog.Logger
+
+	mu sync.Mutex
+	// restreams maps a channel ID to the backend running its restreams.
+	restreams map[string]int
+}
+
+func newTranscoderRouter(pool *transcoderPool, client *http.Client, logger *slog.Logger, attempts int, interval time.Duration) *transcoderRouter {
+	adapters := make([]transcoderAdapter, len(pool.backends))
+	for i, backend := range pool.backends {
+		adapters[i] = newHTTPTranscoderAdapter(backend.BaseURL, backend.Token, client, logger, attempts, interval)
+	}
+	return &transcoderRouter{pool: pool, adapters: adapters, logger: logger, restreams: make(map[string]int)}
+}
+
+// placement picks a backend for a new job and logs the choice.
+func (r *transcoderRouter) placement(kind, channelID string) int {
+	i := r.pool.place()
+	if len(r.pool.backends) > 1 {
+		backend := r.pool.backends[i]
+		r.logger.Info("placed transcoder job",
+			"kind", kind,
+			"channel_id", channelID,
+			"backend", backend.Name,
+			"labels", backend.Labels,
+		)
+	}
+	return i
+}
+
+// StartJobs places the live jobs of a session on one backend and returns
+// their handles.
+func (r *transcoderRouter) StartJobs(ctx context.Context, channelID, sessionID, originURL string, ladder []Rendition, limits models.TranscodeLimits) ([]string, []Rendition, error) {
+	i := r.placement("live", channelID)
+	jobIDs, renditions, err := r.adapters[i].StartJobs(ctx, channelID, sessionID, originURL, ladder, limits)
+	if err != nil {
+		return nil, nil, err
+	}
+	handles := make([]string, len(jobIDs))
+	for n, jobID := range jobIDs {
+		handles[n] = r.pool.handle(i, jobID)
+	}
+	r.mu.Lock()
+	r.restreams[channelID] = i
+	r.mu.Unlock()
+	return handles, renditions, nil
+}
+
+// StopJob stops the job on the backend named by its handle.
+func (r *transcoderRouter) StopJob(ctx context.Context, handle string) (int64, error) {
+	i, jobID, err := r.pool.resolve(handle)
+	if err != nil {
+		return 0, err
+	}
+	return r.adapters[i].StopJob(ctx, jobID)
+}
+
+// StartUpload places an upload job and returns its handle as the job ID.
+func (r *transcoderRouter) StartUpload(ctx context.Context, req uploadJobRequest) (uploadJobResult, error) {
+	i := r.placement("upload", req.ChannelID)
+	result, err := r.adapters[i].StartUpload(ctx, req)
+	if err != nil {
+		return uploadJobResult{}, err
+	}
+	result.JobID = r.pool.handle(i, result.JobID)
+	return result, nil
+}
+
+// UploadStatus asks the backend named by the handle about an upload job.
+func (r *transcoderRouter) UploadStatus(ctx context.Context, handle string) (UploadOutput, error) {
+	i, jobID, err := r.pool.resolve(handle)
+	if err != nil {
+		return UploadOutput{}, fmt.Errorf("%w: %w", ErrUploadOutputUnavailable, err)
+	}
+	return r.adapters[i].UploadStatus(ctx, jobID)
+}
+
+// FetchFrame fetches a frame from the backend named by the handle.
+func (r *transcoderRouter) FetchFrame(ctx context.Context, handle string) (Frame, error) {
+	i, jobID, err := r.pool.resolve(handle)
+	if err != nil {
+		return Frame{}, fmt.Errorf("%w: %w", ErrFrameUnavailable, err)
+	}
+	return r.adapters[i].FetchFrame(ctx, jobID)
+}
+
+// CreateClip cuts a clip on the backend named by the handle.
+func (r *transcoderRouter) CreateClip(ctx context.Context, handle string, durationSeconds int) (LiveClip, error) {
+	i, jobID, err := r.pool.resolve(handle)
+	if err != nil {
+		return LiveClip{}, fmt.Errorf("%w: %w", ErrClipUnavailable, err)
+	}
+	return r.adapters[i].CreateClip(ctx, jobID, durationSeconds)
+}
+
+// StartRestreams runs the restreams on the backend that took the channel's
+// live jobs, or places them when it is not known.
+func (r *transcoderRouter) StartRestreams(ctx context.Context, channelID, sessionID, originURL string, targets []RestreamTarget) error {
+	i, ok := r.restreamBackend(channelID)
+	if !ok {
+		i = r.placement("restream", channelID)
+		r.mu.Lock()
+		r.restreams[channelID] = i
+		r.mu.Unlock()
+	}
+	return r.adapters[i].StartRestreams(ctx, channelID, sessionID, originURL, targets)
+}
+
+// StopRestreams stops the channel's restreams on the backend running them.
+// When that is not known, as after a restart, every healthy backend is asked,
+// and backends without restreams for the channel answer that there is
+// nothing to stop.
+func (r *transcoderRouter) StopRestreams(ctx context.Context, channelID string) error {
+	r.mu.Lock()
+	i, ok := r.restreams[channelID]
+	delete(r.restreams, channelID)
+	r.mu.Unlock()
+	if ok {
+		return r.adapters[i].StopRestreams(ctx, channelID)
+	}
+	var errs []error
+	for _, i := range r.reachable() {
+		if err := r.adapters[i].StopRestreams(ctx, channelID); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.pool.backends[i].Name, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// RestreamStatus reports the channel's restreams from the backend running
+// them, or gathers them from every healthy backend when that is not known.
+func (r *transcoderRouter) RestreamStatus(ctx context.Context, channelID string) ([]RestreamStatus, error) {
+	if i, ok := r.restreamBackend(channelID); ok {
+		return r.adapters[i].RestreamStatus(ctx, channelID)
+	}
+	statuses := []RestreamStatus{}
+	var errs []error
+	for _, i := range r.reachable() {
+		found, err := r.adapters[i].RestreamStatus(ctx, channelID)
+		if err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", r.pool.backends[i].Name, err))
+			continue
+		}
+		statuses = append(statuses, found...)
+	}
+	if len(statuses) == 0 && len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return statuses, nil
+}
+
+func (r *transcoderRouter) restreamBackend(channelID string) (int, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	i, ok := r.restreams[channelID]
+	return i, ok
+}
+
+// reachable lists the backends that passed their last health probe.
+func (r *transcoderRouter) reachable() []int {
+	indexes := make([]int, 0, len(r.adapters))
+	for i := range r.adapters {
+		if r.pool.healthy(i) {
+			indexes = append(indexes, i)
+		}
+	}
+	return indexes
+}
+
+// backendOf returns the name of the backend that owns a job handle.
+func (r *transcoderRouter) backendOf(handle string) string {
+	i, _, err := r.pool.resolve(handle)
+	if err != nil {
+		return ""
+	}
+	return r.pool.backends[i].Name
+}
+
+// jobID returns the backend's own ID for a job handle.
+func (r *transcoderRouter) jobID(handle string) string {
+	_, jobID, err := r.pool.resolve(handle)
+	if err != nil {
+		return handle
+	}
+	return jobID
+}
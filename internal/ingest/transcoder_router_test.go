@@ -0,0 +1,189 @@
+package ingest
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"reflect"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/testsupport/ingeststub"
+)
+
+// sequenceIntn returns 0, 1, 2, ... modulo n, so weighted placement visits
+// every slot of the weight range in turn.
+func sequenceIntn() func(int) int {
+	next := 0
+	return func(n int) int {
+		value := next % n
+		next++
+		return value
+	}
+}
+
+func TestTranscoderPoolPlacesByWeight(t *testing.T) {
+	pool := newTranscoderPool([]TranscoderBackend{{Name: "east", Weight: 3}, {Name: "west", Weight: 1}})
+	pool.intn = sequenceIntn()
+
+	counts := make(map[string]int)
+	for i := 0; i < 400; i++ {
+		counts[pool.backends[pool.place()].Name]++
+	}
+	if counts["east"] != 300 || counts["west"] != 100 {
+		t.Fatalf("expected placements to follow the 3:1 weights, got %v", counts)
+	}
+}
+
+func TestTranscoderPoolPrefersLeastLoadedBackend(t *testing.T) {
+	pool := newTranscoderPool([]TranscoderBackend{{Name: "east", Weight: 1}, {Name: "west", Weight: 2}})
+	pool.intn = func(int) int { t.Fatal("expected no random placement once loads are known"); return 0 }
+	pool.observe(0, true, 1)
+	pool.observe(1, true, 4)
+
+	// west has twice the weight, so it counts as less loaded until it runs
+	// more than twice as many jobs as east.
+	var got []string
+	for i := 0; i < 4; i++ {
+		got = append(got, pool.backends[pool.place()].Name)
+	}
+	if want := []string{"east", "east", "west", "west"}; !reflect.DeepEqual(got, want) {
+		t.Fatalf("expected placements %v, got %v", want, got)
+	}
+
+	pool.observe(1, true, -1)
+	pool.intn = sequenceIntn()
+	if name := pool.backends[pool.place()].Name; name != "east" {
+		t.Fatalf("expected weighted placement when a backend reports no load, got %s", name)
+	}
+}
+
+// newRoutedController builds a controller whose SRS and OME calls go to
+// control and whose transcoder backends are east and west.
+func newRoutedController(control, east, west *ingeststub.ControlPlane) *HTTPController {
+	controller := &HTTPController{
+		config: Config{
+			SRSBaseURL:     control.BaseURL(),
+			SRSToken:       "srs-token",
+			OMEBaseURL:     control.BaseURL(),
+			OMEUsername:    "ome-user",
+			OMEPassword:    "ome-pass",
+			JobToken:       "transcoder-token",
+			LadderProfiles: []Rendition{{Name: "720p", Bitrate: 2400}},
+			Transcoders: []TranscoderBackend{
+				{Name: "east", BaseURL: east.BaseURL()},
+				{Name: "west", BaseURL: west.BaseURL(), Labels: map[string]string{"region": "eu"}},
+			},
+			HTTPRetryInterval: time.Millisecond,
+			HTTPMaxAttempts:   1,
+		},
+	}
+	controller.ensureAdapters()
+	return controller
+}
+
+func TestHTTPControllerRoutesJobCallsToOwningBackend(t *testing.T) {
+	control := ingeststub.Start(ingeststub.Options{OriginURL: "http://origin", PlaybackURL: "https://playback"})
+	t.Cleanup(control.Close)
+	// Both backends hand out the same job ID, so only the handle tells
+	// the jobs apart.
+	east := ingeststub.Start(ingeststub.Options{TranscoderToken: "transcoder-token"})
+	t.Cleanup(east.Close)
+	west := ingeststub.Start(ingeststub.Options{TranscoderToken: "transcoder-token"})
+	t.Cleanup(west.Close)
+
+	controller := newRoutedController(control, east, west)
+	controller.transcoders.intn = func(int) int { return 1 }
+
+	result, err := controller.BootStream(context.Background(), BootParams{ChannelID: "channel-1", StreamKey: "key", SessionID: "session-1"})
+	if err != nil {
+		t.Fatalf("BootStream: %v", err)
+	}
+	if !reflect.DeepEqual(result.JobIDs, []string{"west:job-live-1"}) || result.TranscoderBackend != "west" {
+		t.Fatalf("expected the jobs to be placed on west, got %v on %q", result.JobIDs, result.TranscoderBackend)
+	}
+	if len(east.Journal(ingeststub.EndpointJobStart)) != 0 {
+		t.Fatal("expected no jobs started on east")
+	}
+
+	// A fresh controller has no memory of the placement, as after a
+	// restart; the handle alone routes the stop.
+	restarted := newRoutedController(control, east, west)
+	if err := restarted.ShutdownStream(context.Background(), "channel-1", "session-1", result.JobIDs); err != nil {
+		t.Fatalf("ShutdownStream: %v", err)
+	}
+	stops := west.Journal(ingeststub.EndpointJobStop)
+	if len(stops) != 1 || stops[0].JobID != "job-live-1" {
+		t.Fatalf("expected west to stop job-live-1, got %+v", stops)
+	}
+	if len(east.Journal(ingeststub.EndpointJobStop)) != 0 {
+		t.Fatal("expected east to receive no stop")
+	}
+}
+
+func TestHTTPControllerSkipsUnhealthyBackends(t *testing.T) {
+	control := ingeststub.Start(ingeststub.Options{OriginURL: "http://origin", PlaybackURL: "https://playback"})
+	t.Cleanup(control.Close)
+	east := ingeststub.NewScenario(ingeststub.Options{}).FailFirst(ingeststub.EndpointHealth, 100, http.StatusServiceUnavailable).Start()
+	t.Cleanup(east.Close)
+	west := ingeststub.Start(ingeststub.Options{})
+	t.Cleanup(west.Close)
+
+	controller := newRoutedController(control, east, west)
+	controller.config.HealthEndpoint = "/healthz"
+	controller.transcoders.intn = func(int) int { return 0 }
+
+	statuses := make(map[string]string)
+	for _, status := range controller.HealthChecks(context.Background()) {
+		statuses[status.Component] = status.Status
+	}
+	if statuses["transcoder:east"] != "error" || statuses["transcoder:west"] != "ok" {
+		t.Fatalf("expected a health status per backend, got %v", statuses)
+	}
+	if got := controller.HealthComponents(); !reflect.DeepEqual(got, []string{"srs", "ovenmediaengine", "transcoder:east", "transcoder:west"}) {
+		t.Fatalf("unexpected health components %v", got)
+	}
+
+	for i := 0; i < 3; i++ {
+		result, err := controller.BootStream(context.Background(), BootParams{ChannelID: "channel-1", StreamKey: "key", SessionID: "session-1"})
+		if err != nil {
+			t.Fatalf("BootStream: %v", err)
+		}
+		if result.TranscoderBackend != "west" {
+			t.Fatalf("expected the unhealthy backend to be skipped, got %q", result.TranscoderBackend)
+		}
+	}
+
+	// Once east recovers it takes placements again.
+	controller.transcoders.observe(0, true, -1)
+	result, err := controller.BootStream(context.Background(), BootParams{ChannelID: "channel-2", StreamKey: "key", SessionID: "session-2"})
+	if err != nil {
+		t.Fatalf("BootStream: %v", err)
+	}
+	if result.TranscoderBackend != "east" {
+		t.Fatalf("expected the recovered backend to be used, got %q", result.TranscoderBackend)
+	}
+}
+
+func TestHTTPControllerShutdownContinuesWhenBackendIsGone(t *testing.T) {
+	control := ingeststub.Start(ingeststub.Options{})
+	t.Cleanup(control.Close)
+	east := ingeststub.Start(ingeststub.Options{})
+	t.Cleanup(east.Close)
+	west := ingeststub.Start(ingeststub.Options{})
+	t.Cleanup(west.Close)
+	controller := newRoutedController(control, east, west)
+
+	if _, err := controller.transcoder.StopJob(context.Background(), "south:job-1"); !errors.Is(err, ErrTranscoderBackendUnknown) {
+		t.Fatalf("expected ErrTranscoderBackendUnknown, got %v", err)
+	}
+	if err := controller.ShutdownStream(context.Background(), "channel-1", "session-1", []string{"south:job-1", "east:job-2"}); err != nil {
+		t.Fatalf("expected the shutdown to finish despite the missing backend, got %v", err)
+	}
+	if stops := east.Journal(ingeststub.EndpointJobStop); len(stops) != 1 || stops[0].JobID != "job-2" {
+		t.Fatalf("expected east to stop job-2, got %+v", stops)
+	}
+	if len(control.Journal(ingeststub.EndpointApplicationDelete)) != 1 || len(control.Journal(ingeststub.EndpointChannelDelete)) != 1 {
+		t.Fatal("expected the application and channel to be deleted")
+	}
+}
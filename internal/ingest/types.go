@@ -1,6 +1,13 @@
 package ingest
 
-import "context"
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"bitriver-live/internal/models"
+)
 
 // BootParams captures the information required to start an ingest and
 // transcoding pipeline for a channel.
@@ -20,14 +27,54 @@ type BootParams struct {
 	// on the same channel.
 	SessionID string
 
-	// StreamKey is the secret used by the encoder to authenticate and publish
-	// to the ingest endpoint (e.g., RTMP).
+	// StreamKey identifies the channel's publishing credential at the ingest
+	// endpoint (e.g., RTMP). Storage only retains a hash of the encoder's key,
+	// so this carries the hash; publish authentication happens in the SRS hook.
 	StreamKey string
 
 	// Renditions is an optional list of rendition names passed to the
 	// application adapter. It may be used by the origin (OME) to configure
 	// which renditions are exposed for a particular channel.
 	Renditions []string
+
+	// TranscodeLimits is the channel's override of the platform transcode
+	// caps. The controller applies it on top of Config.TranscodeLimits and
+	// forwards the result to the transcoder, which clamps the ladder to fit.
+	TranscodeLimits *models.TranscodeLimits
+
+	// Restreams are external destinations the transcoder pushes a copy of
+	// the stream to once the transcode is running. Failing to start them is
+	// logged and never fails the boot.
+	Restreams []RestreamTarget
+}
+
+// RestreamTarget is an RTMP destination a live stream is simulcast to.
+type RestreamTarget struct {
+	// ID identifies the target on the platform so its status can be matched
+	// back to it.
+	ID string `json:"id"`
+	// URL is the RTMP or RTMPS ingest URL, without the stream key.
+	URL string `json:"url"`
+	// StreamKey is the plaintext key the destination expects after URL.
+	StreamKey string `json:"streamKey"`
+}
+
+// Restream states reported by the transcoder.
+const (
+	// RestreamConnected means the push is running.
+	RestreamConnected = "connected"
+	// RestreamRetrying means the push exited and is waiting to restart.
+	RestreamRetrying = "retrying"
+	// RestreamFailed means the push exhausted its restart budget.
+	RestreamFailed = "failed"
+)
+
+// RestreamStatus is the state of one restream of a live channel.
+type RestreamStatus struct {
+	TargetID  string `json:"targetId"`
+	State     string `json:"state"`
+	Restarts  int    `json:"restarts"`
+	LastError string `json:"lastError,omitempty"`
 }
 
 // Rendition describes an output profile in the encoding ladder.
@@ -52,6 +99,41 @@ type BootResult struct {
 	PlaybackURL   string      `json:"playbackUrl"`
 	Renditions    []Rendition `json:"renditions"`
 	JobIDs        []string    `json:"jobIds"`
+	// PlaybackEndpoints lists each protocol the origin serves the session
+	// over, most preferred first. PlaybackURL is the first HLS-family entry.
+	PlaybackEndpoints []models.PlaybackEndpoint `json:"playbackEndpoints,omitempty"`
+	// PreviewURL is where the transcoder mirrors the job's latest preview
+	// frame, or empty when the renditions are not served from its public
+	// mirror.
+	PreviewURL string `json:"previewUrl,omitempty"`
+	// TranscoderBackend names the transcoder backend the jobs were placed
+	// on.
+	TranscoderBackend string `json:"transcoderBackend,omitempty"`
+}
+
+// LivePreviewFrameName is the file the transcoder refreshes with a still
+// frame of each live job.
+const LivePreviewFrameName = "preview.jpg"
+
+// LivePreviewURL derives the public URL of a live job's preview frame from
+// the manifest URLs the transcoder returned. Live manifests are mirrored at
+// <public base>/live/<jobID>/..., so the frame sits beside them at
+// <public base>/live/<jobID>/preview.jpg. It returns "" when no manifest is
+// under a job's mirror directory.
+func LivePreviewURL(jobIDs []string, renditions []Rendition) string {
+	for _, jobID := range jobIDs {
+		jobID = strings.TrimSpace(jobID)
+		if jobID == "" {
+			continue
+		}
+		marker := "/live/" + jobID + "/"
+		for _, rendition := range renditions {
+			if idx := strings.Index(rendition.ManifestURL, marker); idx > 0 {
+				return rendition.ManifestURL[:idx+len(marker)] + LivePreviewFrameName
+			}
+		}
+	}
+	return ""
 }
 
 // UploadTranscodeParams describes the work required to convert a pre-uploaded
@@ -74,6 +156,11 @@ type UploadTranscodeParams struct {
 	// used in logs or downstream storage.
 	Filename string
 
+	// ExpectedHash is the optional hex-encoded SHA-256 the source must match.
+	// The transcoder rejects the upload when the media at SourceURL has
+	// changed since it was received.
+	ExpectedHash string
+
 	// Renditions describes the desired output ladder for the VOD asset.
 	Renditions []Rendition
 }
@@ -86,6 +173,186 @@ type UploadTranscodeResult struct {
 	PlaybackURL string      `json:"playbackUrl"`
 	Renditions  []Rendition `json:"renditions"`
 	JobID       string      `json:"jobId"`
+	Media       *MediaInfo  `json:"media,omitempty"`
+	// ThumbnailURL is the poster frame the transcoder grabbed, when it
+	// generates one.
+	ThumbnailURL string `json:"thumbnailUrl,omitempty"`
+}
+
+// MediaInfo describes the source media properties discovered by the
+// transcoder when it probes an upload before accepting the job.
+type MediaInfo struct {
+	DurationSeconds float64 `json:"durationSeconds,omitempty"`
+	Width           int     `json:"width,omitempty"`
+	Height          int     `json:"height,omitempty"`
+	VideoCodec      string  `json:"videoCodec,omitempty"`
+	AudioCodec      string  `json:"audioCodec,omitempty"`
+	// Bitrate is the overall source bitrate in kbps, when known.
+	Bitrate int `json:"bitrate,omitempty"`
+}
+
+// UploadRejectedError reports that the transcoder refused an upload because
+// the source failed validation (for example it is not a video or exceeds the
+// configured limits). Reason is a machine-readable code such as "not_video"
+// and Message is suitable for display to creators. Retrying will not help.
+type UploadRejectedError struct {
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+}
+
+func (e *UploadRejectedError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("upload rejected: %s", e.Reason)
+	}
+	return fmt.Sprintf("upload rejected: %s", e.Message)
+}
+
+// Reasons reported by TranscodeLimitError.
+const (
+	LimitReasonResolution = "resolution_exceeded"
+	LimitReasonBitrate    = "bitrate_exceeded"
+	LimitReasonRenditions = "renditions_exceeded"
+)
+
+// TranscodeLimitError reports a rendition ladder that breaks the transcode
+// caps. Reason is one of the LimitReason constants, Limit is the cap and
+// Value the offending figure (pixels, kbps, or renditions). The transcoder
+// returns it as a 422 body and the API relays it to creators.
+type TranscodeLimitError struct {
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+	Limit   int    `json:"limit"`
+	Value   int    `json:"value"`
+}
+
+func (e *TranscodeLimitError) Error() string {
+	if e.Message == "" {
+		return fmt.Sprintf("transcode limit exceeded: %s", e.Reason)
+	}
+	return e.Message
+}
+
+// Steps reported by BootStepError.
+const (
+	BootStepChannel     = "srs_channel"
+	BootStepApplication = "ome_application"
+	BootStepJobs        = "transcoder_jobs"
+)
+
+// BootStepError reports the BootStream step that failed. Step is one of the
+// BootStep constants and Err the step's error, which errors.Is and errors.As
+// still see.
+type BootStepError struct {
+	Step string
+	Err  error
+}
+
+func (e *BootStepError) Error() string {
+	return fmt.Sprintf("boot %s: %v", e.Step, e.Err)
+}
+
+func (e *BootStepError) Unwrap() error {
+	return e.Err
+}
+
+// ErrTranscoderOutOfStorage reports that the transcoder refused a job with
+// 507 Insufficient Storage because its disk is below the configured floor.
+// Retrying does not help until an operator frees space.
+var ErrTranscoderOutOfStorage = errors.New("transcoder out of storage")
+
+// ErrFrameUnavailable reports that no still frame could be captured, for
+// example because the job is unknown or has not produced any video yet.
+var ErrFrameUnavailable = errors.New("frame unavailable")
+
+// Frame is a still image captured from a live transcoder job.
+type Frame struct {
+	Data        []byte
+	ContentType string
+}
+
+// FrameCapturer is implemented by controllers that can fetch a still frame
+// from a running transcoder job. It is optional: callers type-assert for it
+// and treat controllers without it as never having a frame.
+type FrameCapturer interface {
+	// CaptureFrame returns the most recent still for jobID, or
+	// ErrFrameUnavailable when the job has none.
+	CaptureFrame(ctx context.Context, jobID string) (Frame, error)
+}
+
+// ErrClipUnavailable reports that a live clip could not be cut, for example
+// because the job is unknown, has not buffered any output yet, or the
+// transcoder has live clips disabled.
+var ErrClipUnavailable = errors.New("clip unavailable")
+
+// LiveClip is a clip cut from the buffered output of a live transcoder job.
+type LiveClip struct {
+	ID              string
+	PlaybackURL     string
+	DurationSeconds int
+}
+
+// LiveClipper is implemented by controllers that can cut clips from running
+// transcoder jobs. Like FrameCapturer it is optional.
+type LiveClipper interface {
+	// CaptureClip returns a clip of the newest durationSeconds of jobID, or
+	// ErrClipUnavailable when the job has nothing to clip.
+	CaptureClip(ctx context.Context, jobID string, durationSeconds int) (LiveClip, error)
+}
+
+// RestreamReporter is implemented by controllers that can report on the
+// restreams of a live channel. Like FrameCapturer it is optional.
+type RestreamReporter interface {
+	// RestreamStatus returns the state of each restream of channelID. A
+	// channel without restreams reports an empty slice.
+	RestreamStatus(ctx context.Context, channelID string) ([]RestreamStatus, error)
+}
+
+// States reported in UploadOutput.Status.
+const (
+	UploadOutputRunning   = "running"
+	UploadOutputCompleted = "completed"
+	UploadOutputFailed    = "failed"
+)
+
+// ErrUploadOutputUnavailable reports that the transcoder cannot describe an
+// upload job, for example because it has forgotten the job or predates
+// reporting output sizes.
+var ErrUploadOutputUnavailable = errors.New("upload output unavailable")
+
+// UploadOutput describes an upload transcode job. Bytes is the size of the
+// transcoded output and is only meaningful once Status is
+// UploadOutputCompleted.
+type UploadOutput struct {
+	Status string
+	Bytes  int64
+}
+
+// StreamOutputReporter is implemented by controllers that can report how
+// much output a stream's transcoder jobs wrote. Like FrameCapturer it is
+// optional; see ShutdownStreamWithOutput.
+type StreamOutputReporter interface {
+	// ShutdownStreamOutput behaves like Controller.ShutdownStream and also
+	// returns the combined size of the stopped jobs' output.
+	ShutdownStreamOutput(ctx context.Context, channelID, sessionID string, jobIDs []string) (int64, error)
+}
+
+// UploadOutputReporter is implemented by controllers that can report when an
+// upload transcode finished and how large its output is. Like FrameCapturer
+// it is optional.
+type UploadOutputReporter interface {
+	// UploadOutput describes the upload job jobID returned by
+	// TranscodeUpload, or returns ErrUploadOutputUnavailable.
+	UploadOutput(ctx context.Context, jobID string) (UploadOutput, error)
+}
+
+// ShutdownStreamWithOutput tears the stream down through controller and
+// returns the size of its output when the controller can report it, or zero
+// when it cannot.
+func ShutdownStreamWithOutput(ctx context.Context, controller Controller, channelID, sessionID string, jobIDs []string) (int64, error) {
+	if reporter, ok := controller.(StreamOutputReporter); ok {
+		return reporter.ShutdownStreamOutput(ctx, channelID, sessionID, jobIDs)
+	}
+	return 0, controller.ShutdownStream(ctx, channelID, sessionID, jobIDs)
 }
 
 // HealthStatus captures the availability/health of an external dependency
@@ -127,6 +394,17 @@ type Controller interface {
 	TranscodeUpload(ctx context.Context, params UploadTranscodeParams) (UploadTranscodeResult, error)
 }
 
+// ComponentHealthChecker is implemented by controllers that can probe each
+// ingest dependency on its own, letting callers such as HealthPoller back off
+// from a failing dependency without delaying checks of the others.
+type ComponentHealthChecker interface {
+	// HealthComponents names the dependencies HealthChecks reports on.
+	HealthComponents() []string
+
+	// CheckComponentHealth probes the named dependency.
+	CheckComponentHealth(ctx context.Context, component string) HealthStatus
+}
+
 // NoopController is a Controller implementation used in tests and in
 // deployments where ingest is not configured or intentionally disabled.
 //
@@ -0,0 +1,55 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"bitriver-live/internal/chat"
+	"bitriver-live/internal/storage"
+)
+
+// ChannelGrantSweepJob deletes channel moderator and editor grants whose
+// expiry has passed. Expired grants already stop applying when checked; the
+// sweep tidies them away and tells chat that the moderators are gone.
+const ChannelGrantSweepJob = "channels.sweep_expired_grants"
+
+// ChannelGrantSweeper deletes expired grants. storage.Repository satisfies
+// it.
+type ChannelGrantSweeper interface {
+	PurgeExpiredChannelGrants() (storage.ExpiredChannelGrants, error)
+}
+
+// ModeratorAnnouncer tells chat rooms about moderator changes.
+// *chat.Gateway satisfies it.
+type ModeratorAnnouncer interface {
+	AnnounceModerator(ctx context.Context, moderator chat.ModeratorEvent) error
+}
+
+// ChannelGrantSweep returns the handler for ChannelGrantSweepJob. Each
+// removed moderator is announced through announcer when it is non-nil.
+func ChannelGrantSweep(sweeper ChannelGrantSweeper, announcer ModeratorAnnouncer) Handler {
+	return func(ctx context.Context, _ storage.Job) error {
+		expired, err := sweeper.PurgeExpiredChannelGrants()
+		if err != nil {
+			return err
+		}
+		if announcer == nil {
+			return nil
+		}
+		var errs []error
+		for _, moderator := range expired.Moderators {
+			err := announcer.AnnounceModerator(ctx, chat.ModeratorEvent{
+				Action:    chat.ModeratorActionRemoved,
+				ChannelID: moderator.ChannelID,
+				UserID:    moderator.UserID,
+				ExpiresAt: moderator.ExpiresAt,
+				Reason:    chat.ModeratorReasonExpired,
+			})
+			if err != nil {
+				errs = append(errs, fmt.Errorf("announce expired moderator %s on %s: %w", moderator.UserID, moderator.ChannelID, err))
+			}
+		}
+		return errors.Join(errs...)
+	}
+}
@@ -0,0 +1,76 @@
+package jobs
+
+import (
+	"context"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/chat"
+	"bitriver-live/internal/storage"
+)
+
+type recordingAnnouncer struct {
+	events []chat.ModeratorEvent
+}
+
+func (a *recordingAnnouncer) AnnounceModerator(_ context.Context, moderator chat.ModeratorEvent) error {
+	a.events = append(a.events, moderator)
+	return nil
+}
+
+func TestChannelGrantSweepRemovesAndAnnouncesExpiredModerators(t *testing.T) {
+	now := time.Date(2026, 3, 1, 20, 0, 0, 0, time.UTC)
+	store, err := storage.NewStorage(filepath.Join(t.TempDir(), "store.json"), storage.WithGrantClock(func() time.Time { return now }))
+	if err != nil {
+		t.Fatalf("NewStorage: %v", err)
+	}
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	guest, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Guest", Email: "guest@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	host, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Host", Email: "host@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Studio", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	expiresAt := now.Add(time.Hour)
+	if _, err := store.GrantChannelModerator(channel.ID, guest.ID, owner.ID, &expiresAt); err != nil {
+		t.Fatalf("GrantChannelModerator: %v", err)
+	}
+	if _, err := store.GrantChannelModerator(channel.ID, host.ID, owner.ID, nil); err != nil {
+		t.Fatalf("GrantChannelModerator: %v", err)
+	}
+
+	announcer := &recordingAnnouncer{}
+	sweep := ChannelGrantSweep(store, announcer)
+	if err := sweep(context.Background(), storage.Job{}); err != nil {
+		t.Fatalf("sweep before expiry: %v", err)
+	}
+	if len(announcer.events) != 0 {
+		t.Fatalf("expected nothing announced before expiry, got %+v", announcer.events)
+	}
+
+	now = expiresAt
+	if err := sweep(context.Background(), storage.Job{}); err != nil {
+		t.Fatalf("sweep after expiry: %v", err)
+	}
+	if len(announcer.events) != 1 {
+		t.Fatalf("expected one announcement, got %+v", announcer.events)
+	}
+	event := announcer.events[0]
+	if event.Action != chat.ModeratorActionRemoved || event.Reason != chat.ModeratorReasonExpired || event.UserID != guest.ID || event.ChannelID != channel.ID {
+		t.Fatalf("unexpected announcement %+v", event)
+	}
+	moderators, err := store.ListChannelModerators(channel.ID)
+	if err != nil || len(moderators) != 1 || moderators[0].UserID != host.ID {
+		t.Fatalf("expected only the permanent moderator to remain, got %+v (err %v)", moderators, err)
+	}
+}
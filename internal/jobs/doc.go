@@ -0,0 +1,6 @@
+// Package jobs runs background work from the persistent job queue in
+// internal/storage. A Pool claims due jobs, dispatches them to handlers
+// registered by job type, and retries failures with exponential backoff
+// until a job runs out of attempts. Because claims are leased, jobs held by
+// a process that crashes are picked up again by any other pool.
+package jobs
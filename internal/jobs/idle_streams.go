@@ -0,0 +1,49 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+// IdleStreamStopJob stops live sessions whose ingest server has reported no
+// media for longer than the idle timeout, so an encoder that dropped out
+// does not leave its channel live indefinitely.
+const IdleStreamStopJob = "streams.stop_idle"
+
+// IdleStreamStopper finds and stops interrupted sessions. storage.Repository
+// satisfies it.
+type IdleStreamStopper interface {
+	ListInterruptedStreamSessions(interruptedBefore time.Time) ([]models.StreamSession, error)
+	CurrentStreamSession(channelID string) (models.StreamSession, bool)
+	StopStreamContext(ctx context.Context, channelID string, peakConcurrent int) (models.StreamSession, error)
+}
+
+// IdleStreamStop returns the handler for IdleStreamStopJob, stopping
+// sessions interrupted for at least timeout.
+func IdleStreamStop(stopper IdleStreamStopper, timeout time.Duration) Handler {
+	return func(ctx context.Context, _ storage.Job) error {
+		cutoff := time.Now().Add(-timeout)
+		sessions, err := stopper.ListInterruptedStreamSessions(cutoff)
+		if err != nil {
+			return err
+		}
+		var errs []error
+		for _, session := range sessions {
+			// The encoder may have reconnected, or the stream been
+			// restarted, since the sessions were listed.
+			current, ok := stopper.CurrentStreamSession(session.ChannelID)
+			if !ok || current.ID != session.ID || current.InterruptedAt == nil || current.InterruptedAt.After(cutoff) {
+				continue
+			}
+			if _, err := stopper.StopStreamContext(ctx, session.ChannelID, 0); err != nil && !errors.Is(err, storage.ErrChannelNotLive) {
+				errs = append(errs, fmt.Errorf("stop channel %s: %w", session.ChannelID, err))
+			}
+		}
+		return errors.Join(errs...)
+	}
+}
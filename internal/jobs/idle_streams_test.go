@@ -0,0 +1,47 @@
+package jobs
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/storage"
+)
+
+func TestIdleStreamStopEndsSessionsPastTheTimeout(t *testing.T) {
+	store := newTestStore(t)
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	startInterrupted := func(title string, since time.Duration) string {
+		t.Helper()
+		channel, err := store.CreateChannel(owner.ID, title, "gaming", nil)
+		if err != nil {
+			t.Fatalf("CreateChannel: %v", err)
+		}
+		if _, err := store.StartStream(channel.ID, []string{"720p"}); err != nil {
+			t.Fatalf("StartStream: %v", err)
+		}
+		if _, err := store.RecordStreamMedia(channel.ID, true, time.Now().Add(-time.Hour)); err != nil {
+			t.Fatalf("RecordStreamMedia: %v", err)
+		}
+		if _, err := store.RecordStreamMedia(channel.ID, false, time.Now().Add(-since)); err != nil {
+			t.Fatalf("RecordStreamMedia: %v", err)
+		}
+		return channel.ID
+	}
+	idle := startInterrupted("Idle", 5*time.Minute)
+	grace := startInterrupted("Grace", 30*time.Second)
+
+	if err := IdleStreamStop(store, 2*time.Minute)(context.Background(), storage.Job{}); err != nil {
+		t.Fatalf("IdleStreamStop: %v", err)
+	}
+	if _, live := store.CurrentStreamSession(idle); live {
+		t.Fatal("expected the stream idle past the timeout to be stopped")
+	}
+	session, live := store.CurrentStreamSession(grace)
+	if !live || session.InterruptedAt == nil {
+		t.Fatalf("expected the stream within its grace period to stay interrupted, got %+v", session)
+	}
+}
@@ -0,0 +1,385 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"bitriver-live/internal/observability/health"
+	"bitriver-live/internal/storage"
+)
+
+const (
+	defaultWorkers      = 2
+	defaultPollInterval = time.Second
+	defaultLease        = 5 * time.Minute
+	defaultMaxAttempts  = 5
+	defaultBaseBackoff  = 10 * time.Second
+	defaultMaxBackoff   = 30 * time.Minute
+)
+
+// Store exposes the queue operations a Pool needs. storage.Repository
+// satisfies it.
+type Store interface {
+	EnqueueJob(params storage.EnqueueJobParams) (storage.Job, error)
+	ClaimDueJobs(workerID string, limit int, lease time.Duration) ([]storage.Job, error)
+	CompleteJob(id, workerID string) error
+	FailJob(id, workerID, message string, retryAt time.Time) (storage.Job, error)
+}
+
+// Handler runs one job. Returning an error fails the attempt; wrap it with
+// Permanent to skip the remaining attempts. The context is cancelled when
+// the job's lease runs out or the pool is forced to stop.
+type Handler func(ctx context.Context, job storage.Job) error
+
+type permanentError struct {
+	err error
+}
+
+func (e *permanentError) Error() string { return e.err.Error() }
+
+func (e *permanentError) Unwrap() error { return e.err }
+
+// Permanent marks err as not worth retrying.
+func Permanent(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &permanentError{err: err}
+}
+
+// IsPermanent reports whether err was marked with Permanent.
+func IsPermanent(err error) bool {
+	var permanent *permanentError
+	return errors.As(err, &permanent)
+}
+
+// Config tunes a Pool. Zero values fall back to the package defaults.
+type Config struct {
+	Store Store
+	// WorkerID identifies this process in job claims. It defaults to the
+	// hostname and process id; each worker goroutine appends its index.
+	WorkerID string
+	// Workers is how many jobs run concurrently.
+	Workers int
+	// PollInterval is how long idle workers wait before checking for due
+	// jobs again.
+	PollInterval time.Duration
+	// Lease is how long a claimed job may run before it is considered
+	// abandoned and handed to another worker. Handlers are cancelled when
+	// it expires.
+	Lease time.Duration
+	// MaxAttempts is how many times a job runs before it is marked failed.
+	MaxAttempts int
+	// BaseBackoff is the delay before the first retry; each later retry
+	// waits twice as long as the one before, up to MaxBackoff.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	Logger      *slog.Logger
+	// Health registers the pool as the "jobs" background component. Nil
+	// disables heartbeat reporting.
+	Health *health.Registry
+}
+
+// Pool runs registered handlers against jobs claimed from the queue.
+type Pool struct {
+	store        Store
+	workerID     string
+	workers      int
+	pollInterval time.Duration
+	lease        time.Duration
+	maxAttempts  int
+	baseBackoff  time.Duration
+	maxBackoff   time.Duration
+	logger       *slog.Logger
+	health       *health.Component
+
+	// ctx stops the workers from claiming more jobs; handlerCtx cancels
+	// handlers that are still running when Shutdown gives up waiting.
+	ctx           context.Context
+	cancel        context.CancelFunc
+	handlerCtx    context.Context
+	handlerCancel context.CancelFunc
+	wg            sync.WaitGroup
+
+	mu        sync.RWMutex
+	handlers  map[string]Handler
+	schedules map[string]time.Duration
+	started   bool
+}
+
+// NewPool configures a pool. Handlers must be registered before Start.
+func NewPool(cfg Config) *Pool {
+	workerID := strings.TrimSpace(cfg.WorkerID)
+	if workerID == "" {
+		host, err := os.Hostname()
+		if err != nil || host == "" {
+			host = "bitriver"
+		}
+		workerID = fmt.Sprintf("%s-%d", host, os.Getpid())
+	}
+	pool := &Pool{
+		store:        cfg.Store,
+		workerID:     workerID,
+		workers:      cfg.Workers,
+		pollInterval: cfg.PollInterval,
+		lease:        cfg.Lease,
+		maxAttempts:  cfg.MaxAttempts,
+		baseBackoff:  cfg.BaseBackoff,
+		maxBackoff:   cfg.MaxBackoff,
+		logger:       cfg.Logger,
+		handlers:     make(map[string]Handler),
+		schedules:    make(map[string]time.Duration),
+	}
+	if pool.workers <= 0 {
+		pool.workers = defaultWorkers
+	}
+	if pool.pollInterval <= 0 {
+		pool.pollInterval = defaultPollInterval
+	}
+	if pool.lease <= 0 {
+		pool.lease = defaultLease
+	}
+	if pool.maxAttempts <= 0 {
+		pool.maxAttempts = defaultMaxAttempts
+	}
+	if pool.baseBackoff <= 0 {
+		pool.baseBackoff = defaultBaseBackoff
+	}
+	if pool.maxBackoff <= 0 {
+		pool.maxBackoff = defaultMaxBackoff
+	}
+	if pool.maxBackoff < pool.baseBackoff {
+		pool.maxBackoff = pool.baseBackoff
+	}
+	if pool.logger == nil {
+		pool.logger = slog.Default()
+	}
+	// Workers cannot heartbeat while a handler runs, so the stall threshold
+	// allows for a full lease.
+	pool.health = cfg.Health.RegisterComponent("jobs", health.WithStaleAfter(pool.lease+health.DefaultStaleAfter))
+	pool.ctx, pool.cancel = context.WithCancel(context.Background())
+	pool.handlerCtx, pool.handlerCancel = context.WithCancel(context.Background())
+	return pool
+}
+
+// Register routes jobs of jobType to handler. Each type may be registered
+// once, and only before the pool starts.
+func (p *Pool) Register(jobType string, handler Handler) error {
+	jobType = strings.TrimSpace(jobType)
+	if jobType == "" {
+		return errors.New("job type is required")
+	}
+	if handler == nil {
+		return fmt.Errorf("handler for job type %q is nil", jobType)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.started {
+		return fmt.Errorf("cannot register job type %q after the pool started", jobType)
+	}
+	if _, exists := p.handlers[jobType]; exists {
+		return fmt.Errorf("job type %q is already registered", jobType)
+	}
+	p.handlers[jobType] = handler
+	return nil
+}
+
+// Schedule runs jobType every interval. The pool queues the first run when
+// it starts and the next one each time a run finishes, keyed so that pools
+// in several processes share a single schedule.
+func (p *Pool) Schedule(jobType string, every time.Duration) error {
+	jobType = strings.TrimSpace(jobType)
+	if every <= 0 {
+		return fmt.Errorf("schedule for job type %q must have a positive interval", jobType)
+	}
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.started {
+		return fmt.Errorf("cannot schedule job type %q after the pool started", jobType)
+	}
+	if _, ok := p.handlers[jobType]; !ok {
+		return fmt.Errorf("job type %q is not registered", jobType)
+	}
+	p.schedules[jobType] = every
+	return nil
+}
+
+func scheduleKey(jobType string) string {
+	return "schedule:" + jobType
+}
+
+// Start queues scheduled jobs and launches the workers.
+func (p *Pool) Start() error {
+	if p == nil {
+		return nil
+	}
+	if p.store == nil {
+		return errors.New("job store is required")
+	}
+	p.mu.Lock()
+	if p.started {
+		p.mu.Unlock()
+		return nil
+	}
+	p.started = true
+	schedules := make(map[string]time.Duration, len(p.schedules))
+	for jobType, every := range p.schedules {
+		schedules[jobType] = every
+	}
+	p.mu.Unlock()
+
+	for jobType := range schedules {
+		if _, err := p.store.EnqueueJob(storage.EnqueueJobParams{Type: jobType, Key: scheduleKey(jobType)}); err != nil {
+			p.health.SetDegraded(err)
+			p.logger.Error("failed to queue scheduled job", "job_type", jobType, "error", err)
+		}
+	}
+	p.health.Heartbeat()
+	for i := 0; i < p.workers; i++ {
+		p.wg.Add(1)
+		go p.worker(fmt.Sprintf("%s/%d", p.workerID, i))
+	}
+	return nil
+}
+
+// Shutdown stops claiming jobs and waits for running handlers to return.
+// If ctx ends first, the handlers are cancelled and their jobs are left for
+// another worker to reclaim once their leases expire.
+func (p *Pool) Shutdown(ctx context.Context) error {
+	if p == nil {
+		return nil
+	}
+	p.cancel()
+	done := make(chan struct{})
+	go func() {
+		p.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		p.handlerCancel()
+		return nil
+	case <-ctx.Done():
+		p.handlerCancel()
+		return ctx.Err()
+	}
+}
+
+func (p *Pool) worker(workerID string) {
+	defer p.wg.Done()
+	timer := time.NewTimer(0)
+	defer timer.Stop()
+	for {
+		select {
+		case <-p.ctx.Done():
+			return
+		case <-timer.C:
+		}
+		for p.ctx.Err() == nil && p.runNext(workerID) {
+		}
+		p.health.Heartbeat()
+		timer.Reset(p.pollInterval)
+	}
+}
+
+// runNext claims and runs one job, reporting whether there was one.
+func (p *Pool) runNext(workerID string) bool {
+	jobs, err := p.store.ClaimDueJobs(workerID, 1, p.lease)
+	if err != nil {
+		p.health.SetDegraded(err)
+		p.logger.Error("failed to claim jobs", "worker_id", workerID, "error", err)
+		return false
+	}
+	if len(jobs) == 0 {
+		return false
+	}
+	p.run(workerID, jobs[0])
+	p.health.SetHealthy()
+	return true
+}
+
+func (p *Pool) run(workerID string, job storage.Job) {
+	p.mu.RLock()
+	handler, ok := p.handlers[job.Type]
+	p.mu.RUnlock()
+
+	var err error
+	if ok {
+		ctx, cancel := context.WithTimeout(p.handlerCtx, p.lease)
+		err = invoke(ctx, handler, job)
+		cancel()
+	} else {
+		err = fmt.Errorf("no handler registered for job type %q", job.Type)
+	}
+
+	logger := p.logger.With("job_id", job.ID, "job_type", job.Type, "attempt", job.Attempts)
+	if err == nil {
+		if err := p.store.CompleteJob(job.ID, workerID); err != nil {
+			logger.Warn("failed to complete job", "error", err)
+			return
+		}
+		p.reschedule(job)
+		return
+	}
+
+	var retryAt time.Time
+	if !IsPermanent(err) && job.Attempts < p.maxAttempts {
+		retryAt = time.Now().Add(p.backoff(job.Attempts))
+	}
+	if _, failErr := p.store.FailJob(job.ID, workerID, err.Error(), retryAt); failErr != nil {
+		logger.Warn("failed to record job failure", "error", failErr, "job_error", err)
+		return
+	}
+	if !retryAt.IsZero() {
+		logger.Warn("job failed; retrying", "error", err, "retry_at", retryAt)
+		return
+	}
+	logger.Error("job failed", "error", err)
+	p.reschedule(job)
+}
+
+// invoke runs handler, converting a panic into an error so one bad job
+// cannot take down the worker.
+func invoke(ctx context.Context, handler Handler, job storage.Job) (err error) {
+	defer func() {
+		if recovered := recover(); recovered != nil {
+			err = fmt.Errorf("job handler panicked: %v", recovered)
+		}
+	}()
+	return handler(ctx, job)
+}
+
+// reschedule queues the next run of a scheduled job once the current run
+// has finished.
+func (p *Pool) reschedule(job storage.Job) {
+	p.mu.RLock()
+	every, ok := p.schedules[job.Type]
+	p.mu.RUnlock()
+	if !ok || job.Key != scheduleKey(job.Type) {
+		return
+	}
+	params := storage.EnqueueJobParams{Type: job.Type, Key: job.Key, RunAt: time.Now().Add(every)}
+	if _, err := p.store.EnqueueJob(params); err != nil {
+		p.health.SetDegraded(err)
+		p.logger.Error("failed to queue scheduled job", "job_type", job.Type, "error", err)
+	}
+}
+
+// backoff returns the delay before retrying a job that has failed attempt
+// times.
+func (p *Pool) backoff(attempt int) time.Duration {
+	delay := p.baseBackoff
+	for i := 1; i < attempt; i++ {
+		delay *= 2
+		if delay >= p.maxBackoff {
+			return p.maxBackoff
+		}
+	}
+	return delay
+}
@@ -0,0 +1,332 @@
+package jobs
+
+import (
+	"context"
+	"errors"
+	"path/filepath"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/storage"
+)
+
+func newTestStore(t *testing.T) *storage.Storage {
+	t.Helper()
+	store, err := storage.NewStorage(filepath.Join(t.TempDir(), "store.json"))
+	if err != nil {
+		t.Fatalf("NewStorage: %v", err)
+	}
+	return store
+}
+
+func newTestPool(t *testing.T, store Store, cfg Config) *Pool {
+	t.Helper()
+	cfg.Store = store
+	if cfg.PollInterval == 0 {
+		cfg.PollInterval = 5 * time.Millisecond
+	}
+	if cfg.BaseBackoff == 0 {
+		cfg.BaseBackoff = 5 * time.Millisecond
+	}
+	pool := NewPool(cfg)
+	t.Cleanup(func() {
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		defer cancel()
+		pool.Shutdown(ctx)
+	})
+	return pool
+}
+
+func waitFor(t *testing.T, what string, cond func() bool) {
+	t.Helper()
+	deadline := time.Now().Add(3 * time.Second)
+	for !cond() {
+		if time.Now().After(deadline) {
+			t.Fatalf("timed out waiting for %s", what)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
+
+func TestRegisterRejectsInvalidHandlers(t *testing.T) {
+	pool := newTestPool(t, newTestStore(t), Config{})
+	noop := func(context.Context, storage.Job) error { return nil }
+
+	if err := pool.Register(" ", noop); err == nil {
+		t.Fatal("expected error for empty job type")
+	}
+	if err := pool.Register("test.nil", nil); err == nil {
+		t.Fatal("expected error for nil handler")
+	}
+	if err := pool.Register("test.once", noop); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := pool.Register("test.once", noop); err == nil {
+		t.Fatal("expected error for duplicate registration")
+	}
+	if err := pool.Schedule("test.unknown", time.Minute); err == nil {
+		t.Fatal("expected error scheduling an unregistered job type")
+	}
+	if err := pool.Schedule("test.once", 0); err == nil {
+		t.Fatal("expected error for non-positive schedule interval")
+	}
+	if err := pool.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := pool.Register("test.late", noop); err == nil {
+		t.Fatal("expected error registering after start")
+	}
+	if err := pool.Schedule("test.once", time.Minute); err == nil {
+		t.Fatal("expected error scheduling after start")
+	}
+}
+
+func TestBackoffDoublesUpToMax(t *testing.T) {
+	pool := NewPool(Config{BaseBackoff: time.Second, MaxBackoff: 5 * time.Second})
+	want := []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second, 5 * time.Second}
+	for i, expected := range want {
+		if got := pool.backoff(i + 1); got != expected {
+			t.Fatalf("attempt %d: expected backoff %s, got %s", i+1, expected, got)
+		}
+	}
+}
+
+func TestPoolRetriesFailingJobsUntilMaxAttempts(t *testing.T) {
+	store := newTestStore(t)
+	pool := newTestPool(t, store, Config{MaxAttempts: 3})
+	var (
+		mu       sync.Mutex
+		attempts []time.Time
+	)
+	if err := pool.Register("test.flaky", func(ctx context.Context, job storage.Job) error {
+		mu.Lock()
+		attempts = append(attempts, time.Now())
+		mu.Unlock()
+		return errors.New("upstream unavailable")
+	}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	job, err := store.EnqueueJob(storage.EnqueueJobParams{Type: "test.flaky"})
+	if err != nil {
+		t.Fatalf("EnqueueJob: %v", err)
+	}
+	if err := pool.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+
+	waitFor(t, "job to fail", func() bool {
+		current, ok := store.GetJob(job.ID)
+		return ok && current.Status == storage.JobStatusFailed
+	})
+	failed, _ := store.GetJob(job.ID)
+	if failed.Attempts != 3 || failed.LastError != "upstream unavailable" {
+		t.Fatalf("unexpected failed job %+v", failed)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(attempts) != 3 {
+		t.Fatalf("expected 3 attempts, got %d", len(attempts))
+	}
+	if gap := attempts[2].Sub(attempts[1]); gap < 10*time.Millisecond {
+		t.Fatalf("expected second retry to back off at least 10ms, waited %s", gap)
+	}
+}
+
+func TestPoolSkipsRetriesForPermanentErrors(t *testing.T) {
+	store := newTestStore(t)
+	pool := newTestPool(t, store, Config{MaxAttempts: 5})
+	if err := pool.Register("test.bad_payload", func(ctx context.Context, job storage.Job) error {
+		return Permanent(errors.New("payload rejected"))
+	}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	job, err := store.EnqueueJob(storage.EnqueueJobParams{Type: "test.bad_payload"})
+	if err != nil {
+		t.Fatalf("EnqueueJob: %v", err)
+	}
+	if err := pool.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	waitFor(t, "job to fail", func() bool {
+		current, ok := store.GetJob(job.ID)
+		return ok && current.Status == storage.JobStatusFailed
+	})
+	if failed, _ := store.GetJob(job.ID); failed.Attempts != 1 {
+		t.Fatalf("expected a single attempt, got %+v", failed)
+	}
+}
+
+func TestPoolRecoversPanickingHandlers(t *testing.T) {
+	store := newTestStore(t)
+	pool := newTestPool(t, store, Config{MaxAttempts: 1})
+	if err := pool.Register("test.panic", func(ctx context.Context, job storage.Job) error {
+		panic("boom")
+	}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	job, err := store.EnqueueJob(storage.EnqueueJobParams{Type: "test.panic"})
+	if err != nil {
+		t.Fatalf("EnqueueJob: %v", err)
+	}
+	if err := pool.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	waitFor(t, "job to fail", func() bool {
+		current, ok := store.GetJob(job.ID)
+		return ok && current.Status == storage.JobStatusFailed
+	})
+}
+
+func TestPoolsShareJobsWithoutRunningThemTwice(t *testing.T) {
+	store := newTestStore(t)
+	var (
+		mu   sync.Mutex
+		runs = make(map[string]int)
+	)
+	handler := func(ctx context.Context, job storage.Job) error {
+		mu.Lock()
+		runs[job.ID]++
+		mu.Unlock()
+		time.Sleep(time.Millisecond)
+		return nil
+	}
+	const jobCount = 40
+	for i := 0; i < jobCount; i++ {
+		if _, err := store.EnqueueJob(storage.EnqueueJobParams{Type: "test.contention"}); err != nil {
+			t.Fatalf("EnqueueJob: %v", err)
+		}
+	}
+	for _, workerID := range []string{"pool-a", "pool-b"} {
+		pool := newTestPool(t, store, Config{WorkerID: workerID, Workers: 4})
+		if err := pool.Register("test.contention", handler); err != nil {
+			t.Fatalf("Register: %v", err)
+		}
+		if err := pool.Start(); err != nil {
+			t.Fatalf("Start: %v", err)
+		}
+	}
+
+	waitFor(t, "all jobs to run", func() bool {
+		mu.Lock()
+		defer mu.Unlock()
+		return len(runs) == jobCount
+	})
+	time.Sleep(20 * time.Millisecond)
+	mu.Lock()
+	defer mu.Unlock()
+	for id, count := range runs {
+		if count != 1 {
+			t.Fatalf("job %s ran %d times", id, count)
+		}
+	}
+}
+
+func TestPoolReclaimsJobsAbandonedByCrashedWorkers(t *testing.T) {
+	store := newTestStore(t)
+	job, err := store.EnqueueJob(storage.EnqueueJobParams{Type: "test.crash"})
+	if err != nil {
+		t.Fatalf("EnqueueJob: %v", err)
+	}
+	// A worker claims the job and dies without completing or failing it.
+	if _, err := store.ClaimDueJobs("crashed-worker", 1, 50*time.Millisecond); err != nil {
+		t.Fatalf("ClaimDueJobs: %v", err)
+	}
+
+	var ran atomic.Int32
+	pool := newTestPool(t, store, Config{})
+	if err := pool.Register("test.crash", func(ctx context.Context, claimed storage.Job) error {
+		if claimed.Attempts != 2 {
+			t.Errorf("expected reclaimed job on attempt 2, got %d", claimed.Attempts)
+		}
+		ran.Add(1)
+		return nil
+	}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	if err := pool.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	waitFor(t, "abandoned job to complete", func() bool {
+		_, ok := store.GetJob(job.ID)
+		return !ok
+	})
+	if ran.Load() != 1 {
+		t.Fatalf("expected handler to run once, ran %d times", ran.Load())
+	}
+}
+
+func TestScheduledJobsRecur(t *testing.T) {
+	store := newTestStore(t)
+	var runs atomic.Int32
+	newPool := func(workerID string) *Pool {
+		pool := newTestPool(t, store, Config{WorkerID: workerID})
+		if err := pool.Register("test.tick", func(ctx context.Context, job storage.Job) error {
+			runs.Add(1)
+			return nil
+		}); err != nil {
+			t.Fatalf("Register: %v", err)
+		}
+		if err := pool.Schedule("test.tick", 20*time.Millisecond); err != nil {
+			t.Fatalf("Schedule: %v", err)
+		}
+		return pool
+	}
+	// Two processes sharing a schedule must not double its frequency.
+	first, second := newPool("first"), newPool("second")
+	if err := first.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if err := second.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	time.Sleep(150 * time.Millisecond)
+	count := runs.Load()
+	if count < 2 {
+		t.Fatalf("expected scheduled job to recur, ran %d times", count)
+	}
+	if count > 9 {
+		t.Fatalf("expected a single shared schedule, ran %d times", count)
+	}
+}
+
+func TestShutdownWaitsForRunningHandlers(t *testing.T) {
+	store := newTestStore(t)
+	pool := NewPool(Config{Store: store, PollInterval: 5 * time.Millisecond})
+	started := make(chan struct{})
+	release := make(chan struct{})
+	if err := pool.Register("test.slow", func(ctx context.Context, job storage.Job) error {
+		close(started)
+		<-release
+		return nil
+	}); err != nil {
+		t.Fatalf("Register: %v", err)
+	}
+	job, err := store.EnqueueJob(storage.EnqueueJobParams{Type: "test.slow"})
+	if err != nil {
+		t.Fatalf("EnqueueJob: %v", err)
+	}
+	if err := pool.Start(); err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	<-started
+
+	shortCtx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+	}()
+	if err := pool.Shutdown(shortCtx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected shutdown to time out while the handler runs, got %v", err)
+	}
+	ctx, cancelWait := context.WithTimeout(context.Background(), time.Second)
+	defer cancelWait()
+	if err := pool.Shutdown(ctx); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if _, ok := store.GetJob(job.ID); ok {
+		t.Fatal("expected the in-flight job to complete before shutdown returned")
+	}
+}
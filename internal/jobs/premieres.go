@@ -0,0 +1,27 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+// PremiereStartJob publishes recordings whose scheduled premiere time has
+// come. Premieres start within one job interval of their scheduled time but
+// are dated from it, so viewers' playback offsets stay in sync either way.
+const PremiereStartJob = "recordings.start_premieres"
+
+// PremiereStarter publishes due premieres. storage.Repository satisfies it.
+type PremiereStarter interface {
+	StartDuePremieres(now time.Time) ([]models.Recording, error)
+}
+
+// PremiereStart returns the handler for PremiereStartJob.
+func PremiereStart(starter PremiereStarter) Handler {
+	return func(_ context.Context, _ storage.Job) error {
+		_, err := starter.StartDuePremieres(time.Now())
+		return err
+	}
+}
@@ -0,0 +1,25 @@
+package jobs
+
+import (
+	"context"
+
+	"bitriver-live/internal/storage"
+)
+
+// RecordingRetentionJob purges recordings whose retention window has
+// passed. Listing recordings also purges them, but only for the channel
+// being browsed; the scheduled job catches everything else.
+const RecordingRetentionJob = "recordings.purge_expired"
+
+// RecordingPurger deletes expired recordings. storage.Repository satisfies
+// it.
+type RecordingPurger interface {
+	PurgeExpiredRecordings(ctx context.Context) error
+}
+
+// RecordingRetention returns the handler for RecordingRetentionJob.
+func RecordingRetention(purger RecordingPurger) Handler {
+	return func(ctx context.Context, _ storage.Job) error {
+		return purger.PurgeExpiredRecordings(ctx)
+	}
+}
@@ -0,0 +1,27 @@
+package jobs
+
+import (
+	"context"
+	"time"
+
+	"bitriver-live/internal/storage"
+)
+
+// PlatformStatsJob recomputes the platform statistics rollup served by
+// /api/stats and /api/admin/stats. Each run replaces the current UTC day's
+// rollup, so running it more often only refreshes the numbers.
+const PlatformStatsJob = "stats.rollup"
+
+// PlatformStatsRoller computes and stores the platform statistics rollup.
+// storage.Repository satisfies it.
+type PlatformStatsRoller interface {
+	RollupPlatformStats(ctx context.Context, now time.Time) (storage.PlatformStats, error)
+}
+
+// PlatformStatsRollup returns the handler for PlatformStatsJob.
+func PlatformStatsRollup(roller PlatformStatsRoller) Handler {
+	return func(ctx context.Context, _ storage.Job) error {
+		_, err := roller.RollupPlatformStats(ctx, time.Now())
+		return err
+	}
+}
@@ -0,0 +1,11 @@
+// Package mail sends the platform's outbound email.
+//
+// Messages are rendered from the embedded template catalog, each template
+// providing a subject, a plain-text body, and an HTML body. Handlers hand the
+// rendered message to a Queue, which delivers it through a Sender on
+// background workers so no request ever waits on SMTP. Transient delivery
+// failures are retried with exponential backoff; a per-recipient cap drops
+// messages beyond a few per hour so a bug or a burst of events cannot flood
+// an inbox. Deployments without an SMTP relay use LogSender, which only
+// writes the messages to the log.
+package mail
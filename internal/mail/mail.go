@@ -0,0 +1,72 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+)
+
+// Message is one rendered email addressed to a single recipient.
+type Message struct {
+	To      string
+	Subject string
+	Text    string
+	HTML    string
+}
+
+// Sender delivers a message. Implementations report failures worth retrying
+// with errors wrapped by Transient.
+type Sender interface {
+	Send(ctx context.Context, msg Message) error
+}
+
+var (
+	// ErrQueueFull is returned by Queue.Enqueue when the backlog is at
+	// capacity.
+	ErrQueueFull = errors.New("mail queue is full")
+	// ErrRateLimited is returned by Queue.Enqueue when the recipient has
+	// already been sent the maximum number of messages in the window.
+	ErrRateLimited = errors.New("mail recipient rate limit reached")
+	// ErrQueueClosed is returned by Queue.Enqueue after Shutdown.
+	ErrQueueClosed = errors.New("mail queue is closed")
+)
+
+type transientError struct {
+	err error
+}
+
+func (e *transientError) Error() string { return e.err.Error() }
+
+func (e *transientError) Unwrap() error { return e.err }
+
+// Transient marks err as a delivery failure that may succeed when retried.
+func Transient(err error) error {
+	if err == nil {
+		return nil
+	}
+	return &transientError{err: err}
+}
+
+// IsTransient reports whether err was marked with Transient.
+func IsTransient(err error) bool {
+	var transient *transientError
+	return errors.As(err, &transient)
+}
+
+// LogSender writes messages to the log instead of sending them. It is the
+// sender used when no SMTP relay is configured, so development setups can
+// still follow links such as email verification.
+type LogSender struct {
+	Logger *slog.Logger
+}
+
+// Send logs msg and never fails.
+func (s LogSender) Send(_ context.Context, msg Message) error {
+	logger := s.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	logger.Info("email not sent; no SMTP relay configured", "to", msg.To, "subject", msg.Subject, "body", strings.TrimSpace(msg.Text))
+	return nil
+}
@@ -0,0 +1,289 @@
+package mail
+
+import (
+	"context"
+	"errors"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"bitriver-live/internal/observability/metrics"
+)
+
+const (
+	defaultQueueSize       = 256
+	defaultQueueWorkers    = 2
+	defaultMaxAttempts     = 5
+	defaultBaseBackoff     = 30 * time.Second
+	defaultMaxBackoff      = 10 * time.Minute
+	defaultRecipientLimit  = 10
+	defaultRecipientWindow = time.Hour
+	// recipientSweepSize is how many tracked recipients trigger a sweep of
+	// those whose window has passed.
+	recipientSweepSize = 1024
+)
+
+// QueueConfig tunes a Queue. Zero values fall back to the package defaults.
+type QueueConfig struct {
+	Sender Sender
+	// Size caps how many messages may wait for a worker. Enqueue refuses
+	// messages beyond it, and retries that find it full are dropped.
+	Size    int
+	Workers int
+	// MaxAttempts is how many times a message is tried before it is
+	// dropped. Only transient failures are retried.
+	MaxAttempts int
+	// BaseBackoff is the delay before the first retry; each later retry
+	// waits twice as long, up to MaxBackoff.
+	BaseBackoff time.Duration
+	MaxBackoff  time.Duration
+	// RecipientLimit caps how many messages one address is sent per
+	// RecipientWindow. Messages over the cap are refused by Enqueue.
+	RecipientLimit  int
+	RecipientWindow time.Duration
+	Logger          *slog.Logger
+	// Metrics defaults to metrics.Default.
+	Metrics *metrics.Recorder
+	// Now returns the current time. Tests inject a fixed clock; nil falls
+	// back to time.Now.
+	Now func() time.Time
+}
+
+type delivery struct {
+	msg     Message
+	attempt int
+}
+
+// Queue delivers messages on background workers. Enqueue never blocks, so
+// request handlers can send mail without waiting on the relay.
+type Queue struct {
+	sender          Sender
+	items           chan delivery
+	workers         int
+	maxAttempts     int
+	baseBackoff     time.Duration
+	maxBackoff      time.Duration
+	recipientLimit  int
+	recipientWindow time.Duration
+	logger          *slog.Logger
+	metrics         *metrics.Recorder
+	now             func() time.Time
+
+	// sendCtx cancels deliveries still running when Shutdown gives up.
+	sendCtx    context.Context
+	sendCancel context.CancelFunc
+	wg         sync.WaitGroup
+
+	mu         sync.Mutex
+	closed     bool
+	retries    map[*time.Timer]struct{}
+	recipients map[string][]time.Time
+}
+
+// NewQueue starts a queue delivering through cfg.Sender.
+func NewQueue(cfg QueueConfig) *Queue {
+	q := &Queue{
+		sender:          cfg.Sender,
+		workers:         cfg.Workers,
+		maxAttempts:     cfg.MaxAttempts,
+		baseBackoff:     cfg.BaseBackoff,
+		maxBackoff:      cfg.MaxBackoff,
+		recipientLimit:  cfg.RecipientLimit,
+		recipientWindow: cfg.RecipientWindow,
+		logger:          cfg.Logger,
+		metrics:         cfg.Metrics,
+		now:             cfg.Now,
+		retries:         make(map[*time.Timer]struct{}),
+		recipients:      make(map[string][]time.Time),
+	}
+	if q.sender == nil {
+		q.sender = LogSender{Logger: cfg.Logger}
+	}
+	size := cfg.Size
+	if size <= 0 {
+		size = defaultQueueSize
+	}
+	if q.workers <= 0 {
+		q.workers = defaultQueueWorkers
+	}
+	if q.maxAttempts <= 0 {
+		q.maxAttempts = defaultMaxAttempts
+	}
+	if q.baseBackoff <= 0 {
+		q.baseBackoff = defaultBaseBackoff
+	}
+	if q.maxBackoff < q.baseBackoff {
+		q.maxBackoff = max(defaultMaxBackoff, q.baseBackoff)
+	}
+	if q.recipientLimit <= 0 {
+		q.recipientLimit = defaultRecipientLimit
+	}
+	if q.recipientWindow <= 0 {
+		q.recipientWindow = defaultRecipientWindow
+	}
+	if q.logger == nil {
+		q.logger = slog.Default()
+	}
+	if q.metrics == nil {
+		q.metrics = metrics.Default()
+	}
+	if q.now == nil {
+		q.now = time.Now
+	}
+	q.items = make(chan delivery, size)
+	q.sendCtx, q.sendCancel = context.WithCancel(context.Background())
+	for i := 0; i < q.workers; i++ {
+		q.wg.Add(1)
+		go q.work()
+	}
+	return q
+}
+
+// Enqueue schedules msg for delivery and returns immediately. It fails with
+// ErrRateLimited when the recipient reached its cap, ErrQueueFull when the
+// backlog is at capacity, and ErrQueueClosed after Shutdown.
+func (q *Queue) Enqueue(msg Message) error {
+	if strings.TrimSpace(msg.To) == "" {
+		return errors.New("mail recipient is required")
+	}
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		return ErrQueueClosed
+	}
+	key := strings.ToLower(strings.TrimSpace(msg.To))
+	now := q.now()
+	sent := q.recentLocked(key, now)
+	if len(sent) >= q.recipientLimit {
+		q.metrics.ObserveMail("rate_limited")
+		q.logger.Warn("mail recipient rate limit reached", "to", msg.To, "subject", msg.Subject)
+		return ErrRateLimited
+	}
+	select {
+	case q.items <- delivery{msg: msg, attempt: 1}:
+	default:
+		q.metrics.ObserveMail("dropped")
+		q.logger.Error("mail queue is full", "to", msg.To, "subject", msg.Subject)
+		return ErrQueueFull
+	}
+	q.recipients[key] = append(sent, now)
+	q.metrics.ObserveMail("queued")
+	return nil
+}
+
+// recentLocked returns the times key was sent mail within the window,
+// forgetting older ones.
+func (q *Queue) recentLocked(key string, now time.Time) []time.Time {
+	if len(q.recipients) >= recipientSweepSize {
+		for other, times := range q.recipients {
+			if len(times) == 0 || now.Sub(times[len(times)-1]) >= q.recipientWindow {
+				delete(q.recipients, other)
+			}
+		}
+	}
+	times := q.recipients[key]
+	kept := times[:0]
+	for _, at := range times {
+		if now.Sub(at) < q.recipientWindow {
+			kept = append(kept, at)
+		}
+	}
+	if len(kept) == 0 {
+		delete(q.recipients, key)
+		return nil
+	}
+	q.recipients[key] = kept
+	return kept
+}
+
+func (q *Queue) work() {
+	defer q.wg.Done()
+	for d := range q.items {
+		q.deliver(d)
+	}
+}
+
+func (q *Queue) deliver(d delivery) {
+	err := q.sender.Send(q.sendCtx, d.msg)
+	if err == nil {
+		q.metrics.ObserveMail("sent")
+		return
+	}
+	if IsTransient(err) && d.attempt < q.maxAttempts {
+		delay := q.backoff(d.attempt)
+		q.metrics.ObserveMail("retried")
+		q.logger.Warn("mail delivery failed; retrying", "to", d.msg.To, "subject", d.msg.Subject, "attempt", d.attempt, "retry_in", delay, "error", err)
+		q.scheduleRetry(delivery{msg: d.msg, attempt: d.attempt + 1}, delay)
+		return
+	}
+	q.metrics.ObserveMail("failed")
+	q.logger.Error("mail delivery failed", "to", d.msg.To, "subject", d.msg.Subject, "attempts", d.attempt, "error", err)
+}
+
+func (q *Queue) backoff(attempt int) time.Duration {
+	delay := q.baseBackoff
+	for i := 1; i < attempt && delay < q.maxBackoff; i++ {
+		delay *= 2
+	}
+	return min(delay, q.maxBackoff)
+}
+
+func (q *Queue) scheduleRetry(d delivery, delay time.Duration) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	if q.closed {
+		q.metrics.ObserveMail("dropped")
+		return
+	}
+	var timer *time.Timer
+	timer = time.AfterFunc(delay, func() {
+		q.mu.Lock()
+		defer q.mu.Unlock()
+		if _, pending := q.retries[timer]; !pending {
+			return
+		}
+		delete(q.retries, timer)
+		select {
+		case q.items <- d:
+		default:
+			q.metrics.ObserveMail("dropped")
+			q.logger.Error("mail queue is full; dropping retry", "to", d.msg.To, "subject", d.msg.Subject)
+		}
+	})
+	q.retries[timer] = struct{}{}
+}
+
+// Shutdown stops accepting messages, drops pending retries, and waits for
+// the backlog to be delivered. When ctx ends first, deliveries in progress
+// are cancelled and ctx's error is returned.
+func (q *Queue) Shutdown(ctx context.Context) error {
+	q.mu.Lock()
+	if !q.closed {
+		q.closed = true
+		for timer := range q.retries {
+			timer.Stop()
+			q.metrics.ObserveMail("dropped")
+		}
+		if dropped := len(q.retries); dropped > 0 {
+			q.logger.Warn("dropping mail awaiting retry at shutdown", "count", dropped)
+		}
+		q.retries = make(map[*time.Timer]struct{})
+		close(q.items)
+	}
+	q.mu.Unlock()
+
+	done := make(chan struct{})
+	go func() {
+		q.wg.Wait()
+		close(done)
+	}()
+	select {
+	case <-done:
+		q.sendCancel()
+		return nil
+	case <-ctx.Done():
+		q.sendCancel()
+		return ctx.Err()
+	}
+}
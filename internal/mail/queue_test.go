@@ -0,0 +1,125 @@
+package mail_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/mail"
+	"bitriver-live/internal/observability/metrics"
+)
+
+// recordingSender records messages and can block until released.
+type recordingSender struct {
+	mu      sync.Mutex
+	sent    []mail.Message
+	release chan struct{}
+	started chan struct{}
+}
+
+func (s *recordingSender) Send(ctx context.Context, msg mail.Message) error {
+	if s.started != nil {
+		s.started <- struct{}{}
+	}
+	if s.release != nil {
+		select {
+		case <-s.release:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.sent = append(s.sent, msg)
+	return nil
+}
+
+func (s *recordingSender) count() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.sent)
+}
+
+func TestQueueCapsMessagesPerRecipient(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 0, 0, 0, time.UTC)
+	recorder := metrics.New()
+	sender := &recordingSender{}
+	queue := mail.NewQueue(mail.QueueConfig{
+		Sender:          sender,
+		RecipientLimit:  2,
+		RecipientWindow: time.Hour,
+		Metrics:         recorder,
+		Now:             func() time.Time { return now },
+	})
+	defer queue.Shutdown(context.Background())
+
+	for i := 0; i < 2; i++ {
+		if err := queue.Enqueue(mail.Message{To: "ada@example.com", Subject: "hello"}); err != nil {
+			t.Fatalf("Enqueue %d: %v", i, err)
+		}
+	}
+	if err := queue.Enqueue(mail.Message{To: "ADA@example.com", Subject: "hello"}); !errors.Is(err, mail.ErrRateLimited) {
+		t.Fatalf("expected the third message to be rate limited regardless of case, got %v", err)
+	}
+	if err := queue.Enqueue(mail.Message{To: "grace@example.com", Subject: "hello"}); err != nil {
+		t.Fatalf("expected other recipients unaffected, got %v", err)
+	}
+
+	now = now.Add(time.Hour)
+	if err := queue.Enqueue(mail.Message{To: "ada@example.com", Subject: "hello"}); err != nil {
+		t.Fatalf("expected the cap to reset after the window, got %v", err)
+	}
+	if err := queue.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if got := sender.count(); got != 4 {
+		t.Fatalf("expected four delivered messages, got %d", got)
+	}
+	if counts := recorder.MailCounts(); counts["rate_limited"] != 1 || counts["sent"] != 4 {
+		t.Fatalf("unexpected mail counts %v", counts)
+	}
+}
+
+func TestQueueEnqueueDoesNotBlock(t *testing.T) {
+	sender := &recordingSender{release: make(chan struct{}), started: make(chan struct{}, 4)}
+	queue := mail.NewQueue(mail.QueueConfig{Sender: sender, Size: 1, Workers: 1, Metrics: metrics.New()})
+
+	if err := queue.Enqueue(mail.Message{To: "a@example.com"}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	<-sender.started
+	if err := queue.Enqueue(mail.Message{To: "b@example.com"}); err != nil {
+		t.Fatalf("expected the backlog to take one message, got %v", err)
+	}
+	if err := queue.Enqueue(mail.Message{To: "c@example.com"}); !errors.Is(err, mail.ErrQueueFull) {
+		t.Fatalf("expected a full queue to refuse messages without blocking, got %v", err)
+	}
+
+	close(sender.release)
+	if err := queue.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+	if got := sender.count(); got != 2 {
+		t.Fatalf("expected the backlog delivered before shutdown returned, got %d", got)
+	}
+	if err := queue.Enqueue(mail.Message{To: "d@example.com"}); !errors.Is(err, mail.ErrQueueClosed) {
+		t.Fatalf("expected ErrQueueClosed after shutdown, got %v", err)
+	}
+}
+
+func TestQueueShutdownCancelsStuckDeliveries(t *testing.T) {
+	sender := &recordingSender{release: make(chan struct{}), started: make(chan struct{}, 1)}
+	queue := mail.NewQueue(mail.QueueConfig{Sender: sender, Workers: 1, Metrics: metrics.New()})
+	if err := queue.Enqueue(mail.Message{To: "a@example.com"}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	<-sender.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 20*time.Millisecond)
+	defer cancel()
+	if err := queue.Shutdown(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected shutdown to give up at the deadline, got %v", err)
+	}
+}
@@ -0,0 +1,296 @@
+package mail
+
+import (
+	"bytes"
+	"context"
+	"crypto/rand"
+	"crypto/tls"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"mime"
+	"mime/multipart"
+	"mime/quotedprintable"
+	"net"
+	"net/mail"
+	"net/smtp"
+	"net/textproto"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// TLSMode selects how the SMTP connection is encrypted.
+type TLSMode string
+
+const (
+	// TLSModeStartTLS connects in plain text and upgrades with STARTTLS,
+	// refusing servers that do not offer it. It is the default.
+	TLSModeStartTLS TLSMode = "starttls"
+	// TLSModeImplicit connects over TLS from the start, as on port 465.
+	TLSModeImplicit TLSMode = "tls"
+	// TLSModeNone never encrypts. Only use it for a relay on the same host
+	// or private network.
+	TLSModeNone TLSMode = "none"
+)
+
+const defaultSMTPTimeout = 30 * time.Second
+
+// ParseTLSMode validates a TLS mode name. An empty name selects
+// TLSModeStartTLS.
+func ParseTLSMode(value string) (TLSMode, error) {
+	switch mode := TLSMode(strings.ToLower(strings.TrimSpace(value))); mode {
+	case "":
+		return TLSModeStartTLS, nil
+	case TLSModeStartTLS, TLSModeImplicit, TLSModeNone:
+		return mode, nil
+	default:
+		return "", fmt.Errorf("unknown SMTP TLS mode %q (want starttls, tls, or none)", value)
+	}
+}
+
+// SMTPConfig describes the relay an SMTPSender delivers through.
+type SMTPConfig struct {
+	Host string
+	// Port defaults to 587 for STARTTLS, 465 for implicit TLS, and 25
+	// without TLS.
+	Port int
+	// Username and Password enable PLAIN authentication when Username is
+	// set. net/smtp refuses to send them over an unencrypted connection to
+	// anything but localhost.
+	Username string
+	Password string
+	// From is the sender address, optionally with a display name.
+	From string
+	TLS  TLSMode
+	// Timeout bounds each delivery, from dialing to QUIT. Zero uses 30s.
+	Timeout time.Duration
+	// TLSConfig overrides the TLS settings, for example to trust a private
+	// CA. ServerName defaults to Host.
+	TLSConfig *tls.Config
+}
+
+// SMTPSender delivers messages through an SMTP relay, one connection per
+// message.
+type SMTPSender struct {
+	cfg  SMTPConfig
+	from *mail.Address
+	addr string
+}
+
+// NewSMTPSender validates cfg and returns a sender for it.
+func NewSMTPSender(cfg SMTPConfig) (*SMTPSender, error) {
+	cfg.Host = strings.TrimSpace(cfg.Host)
+	if cfg.Host == "" {
+		return nil, errors.New("SMTP host is required")
+	}
+	from, err := mail.ParseAddress(strings.TrimSpace(cfg.From))
+	if err != nil {
+		return nil, fmt.Errorf("invalid SMTP from address %q: %w", cfg.From, err)
+	}
+	mode, err := ParseTLSMode(string(cfg.TLS))
+	if err != nil {
+		return nil, err
+	}
+	cfg.TLS = mode
+	if cfg.Port == 0 {
+		switch mode {
+		case TLSModeImplicit:
+			cfg.Port = 465
+		case TLSModeNone:
+			cfg.Port = 25
+		default:
+			cfg.Port = 587
+		}
+	}
+	if cfg.Port < 0 || cfg.Port > 65535 {
+		return nil, fmt.Errorf("invalid SMTP port %d", cfg.Port)
+	}
+	if cfg.Timeout <= 0 {
+		cfg.Timeout = defaultSMTPTimeout
+	}
+	return &SMTPSender{cfg: cfg, from: from, addr: net.JoinHostPort(cfg.Host, strconv.Itoa(cfg.Port))}, nil
+}
+
+// Send delivers msg. Connection failures and 4xx replies are returned as
+// transient errors; 5xx replies and malformed messages are permanent.
+func (s *SMTPSender) Send(ctx context.Context, msg Message) error {
+	to, err := mail.ParseAddress(msg.To)
+	if err != nil {
+		return fmt.Errorf("invalid recipient %q: %w", msg.To, err)
+	}
+	body, err := buildMessage(s.from, to, msg, time.Now())
+	if err != nil {
+		return err
+	}
+
+	ctx, cancel := context.WithTimeout(ctx, s.cfg.Timeout)
+	defer cancel()
+	conn, err := s.dial(ctx)
+	if err != nil {
+		return Transient(fmt.Errorf("connect to %s: %w", s.addr, err))
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	if err := s.deliver(conn, to.Address, body); err != nil {
+		return classifySMTPError(err)
+	}
+	return nil
+}
+
+func (s *SMTPSender) tlsConfig() *tls.Config {
+	cfg := &tls.Config{MinVersion: tls.VersionTLS12}
+	if s.cfg.TLSConfig != nil {
+		cfg = s.cfg.TLSConfig.Clone()
+	}
+	if cfg.ServerName == "" {
+		cfg.ServerName = s.cfg.Host
+	}
+	return cfg
+}
+
+func (s *SMTPSender) dial(ctx context.Context) (net.Conn, error) {
+	if s.cfg.TLS == TLSModeImplicit {
+		dialer := &tls.Dialer{Config: s.tlsConfig()}
+		return dialer.DialContext(ctx, "tcp", s.addr)
+	}
+	var dialer net.Dialer
+	return dialer.DialContext(ctx, "tcp", s.addr)
+}
+
+func (s *SMTPSender) deliver(conn net.Conn, to string, body []byte) error {
+	client, err := smtp.NewClient(conn, s.cfg.Host)
+	if err != nil {
+		return err
+	}
+	defer client.Close()
+	if s.cfg.TLS == TLSModeStartTLS {
+		if ok, _ := client.Extension("STARTTLS"); !ok {
+			return errors.New("SMTP server does not offer STARTTLS")
+		}
+		if err := client.StartTLS(s.tlsConfig()); err != nil {
+			return err
+		}
+	}
+	if s.cfg.Username != "" {
+		if err := client.Auth(smtp.PlainAuth("", s.cfg.Username, s.cfg.Password, s.cfg.Host)); err != nil {
+			return err
+		}
+	}
+	if err := client.Mail(s.from.Address); err != nil {
+		return err
+	}
+	if err := client.Rcpt(to); err != nil {
+		return err
+	}
+	w, err := client.Data()
+	if err != nil {
+		return err
+	}
+	if _, err := w.Write(body); err != nil {
+		return err
+	}
+	if err := w.Close(); err != nil {
+		return err
+	}
+	return client.Quit()
+}
+
+// classifySMTPError marks 4xx replies and connection-level failures as
+// transient. Anything else, such as a 5xx rejection or a server without
+// STARTTLS, will not improve on retry.
+func classifySMTPError(err error) error {
+	var reply *textproto.Error
+	if errors.As(err, &reply) {
+		if reply.Code >= 400 && reply.Code < 500 {
+			return Transient(err)
+		}
+		return err
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) || errors.Is(err, net.ErrClosed) {
+		return Transient(err)
+	}
+	return err
+}
+
+// buildMessage renders msg as a MIME message with a plain-text part and,
+// when present, an HTML alternative.
+func buildMessage(from, to *mail.Address, msg Message, now time.Time) ([]byte, error) {
+	if strings.ContainsAny(msg.Subject, "\r\n") {
+		return nil, errors.New("mail subject must be a single line")
+	}
+	var buf bytes.Buffer
+	header := textproto.MIMEHeader{}
+	header.Set("From", from.String())
+	header.Set("To", to.String())
+	header.Set("Subject", mime.QEncoding.Encode("utf-8", msg.Subject))
+	header.Set("Date", now.UTC().Format(time.RFC1123Z))
+	header.Set("Message-ID", messageID(from.Address))
+	header.Set("MIME-Version", "1.0")
+
+	if msg.HTML == "" {
+		header.Set("Content-Type", "text/plain; charset=utf-8")
+		header.Set("Content-Transfer-Encoding", "quoted-printable")
+		writeHeader(&buf, header)
+		if err := writeQuotedPrintable(&buf, msg.Text); err != nil {
+			return nil, err
+		}
+		return buf.Bytes(), nil
+	}
+
+	var parts bytes.Buffer
+	writer := multipart.NewWriter(&parts)
+	for _, part := range []struct{ contentType, body string }{
+		{"text/plain; charset=utf-8", msg.Text},
+		{"text/html; charset=utf-8", msg.HTML},
+	} {
+		w, err := writer.CreatePart(textproto.MIMEHeader{
+			"Content-Type":              {part.contentType},
+			"Content-Transfer-Encoding": {"quoted-printable"},
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := writeQuotedPrintable(w, part.body); err != nil {
+			return nil, err
+		}
+	}
+	if err := writer.Close(); err != nil {
+		return nil, err
+	}
+	header.Set("Content-Type", "multipart/alternative; boundary="+writer.Boundary())
+	writeHeader(&buf, header)
+	buf.Write(parts.Bytes())
+	return buf.Bytes(), nil
+}
+
+func writeHeader(buf *bytes.Buffer, header textproto.MIMEHeader) {
+	for _, key := range []string{"From", "To", "Subject", "Date", "Message-ID", "MIME-Version", "Content-Type", "Content-Transfer-Encoding"} {
+		if value := header.Get(key); value != "" {
+			fmt.Fprintf(buf, "%s: %s\r\n", key, value)
+		}
+	}
+	buf.WriteString("\r\n")
+}
+
+func writeQuotedPrintable(w io.Writer, body string) error {
+	qp := quotedprintable.NewWriter(w)
+	if _, err := qp.Write([]byte(body)); err != nil {
+		return err
+	}
+	return qp.Close()
+}
+
+func messageID(from string) string {
+	domain := "localhost"
+	if at := strings.LastIndex(from, "@"); at >= 0 && at < len(from)-1 {
+		domain = from[at+1:]
+	}
+	var raw [12]byte
+	_, _ = rand.Read(raw[:])
+	return "<" + hex.EncodeToString(raw[:]) + "@" + domain + ">"
+}
@@ -0,0 +1,211 @@
+package mail_test
+
+import (
+	"bufio"
+	"context"
+	"net"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/mail"
+	"bitriver-live/internal/observability/metrics"
+)
+
+// fakeSMTPServer speaks enough SMTP for net/smtp. mailReplies holds the
+// replies to successive MAIL commands; once it runs out MAIL succeeds.
+type fakeSMTPServer struct {
+	listener net.Listener
+
+	mu          sync.Mutex
+	mailReplies []string
+	attempts    int
+	delivered   []string
+}
+
+func newFakeSMTPServer(t *testing.T, mailReplies ...string) *fakeSMTPServer {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	server := &fakeSMTPServer{listener: listener, mailReplies: mailReplies}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go server.serve(conn)
+		}
+	}()
+	return server
+}
+
+func (s *fakeSMTPServer) port() int {
+	return s.listener.Addr().(*net.TCPAddr).Port
+}
+
+func (s *fakeSMTPServer) serve(conn net.Conn) {
+	defer conn.Close()
+	reader := bufio.NewReader(conn)
+	reply := func(line string) { _, _ = conn.Write([]byte(line + "\r\n")) }
+	reply("220 fake ESMTP")
+	for {
+		line, err := reader.ReadString('\n')
+		if err != nil {
+			return
+		}
+		command := strings.ToUpper(strings.TrimSpace(line))
+		switch {
+		case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+			reply("250 fake")
+		case strings.HasPrefix(command, "MAIL"):
+			s.mu.Lock()
+			s.attempts++
+			response := "250 OK"
+			if len(s.mailReplies) > 0 {
+				response, s.mailReplies = s.mailReplies[0], s.mailReplies[1:]
+			}
+			s.mu.Unlock()
+			reply(response)
+		case strings.HasPrefix(command, "RCPT"), strings.HasPrefix(command, "RSET"), strings.HasPrefix(command, "NOOP"):
+			reply("250 OK")
+		case command == "DATA":
+			reply("354 go ahead")
+			var body strings.Builder
+			for {
+				dataLine, err := reader.ReadString('\n')
+				if err != nil {
+					return
+				}
+				if dataLine == ".\r\n" {
+					break
+				}
+				body.WriteString(dataLine)
+			}
+			s.mu.Lock()
+			s.delivered = append(s.delivered, body.String())
+			s.mu.Unlock()
+			reply("250 queued")
+		case command == "QUIT":
+			reply("221 bye")
+			return
+		default:
+			reply("502 unsupported")
+		}
+	}
+}
+
+func (s *fakeSMTPServer) snapshot() (int, []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.attempts, append([]string(nil), s.delivered...)
+}
+
+func newTestSender(t *testing.T, server *fakeSMTPServer) *mail.SMTPSender {
+	t.Helper()
+	sender, err := mail.NewSMTPSender(mail.SMTPConfig{
+		Host:    "127.0.0.1",
+		Port:    server.port(),
+		From:    "BitRiver Live <noreply@example.com>",
+		TLS:     mail.TLSModeNone,
+		Timeout: 5 * time.Second,
+	})
+	if err != nil {
+		t.Fatalf("NewSMTPSender: %v", err)
+	}
+	return sender
+}
+
+func TestSMTPSenderDelivers(t *testing.T) {
+	server := newFakeSMTPServer(t)
+	sender := newTestSender(t, server)
+	msg, err := mail.Render(mail.TemplatePasswordChanged, "ada@example.com", mail.PasswordChangedData{DisplayName: "Ada", ChangedAt: time.Now()})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if err := sender.Send(context.Background(), msg); err != nil {
+		t.Fatalf("Send: %v", err)
+	}
+	_, delivered := server.snapshot()
+	if len(delivered) != 1 {
+		t.Fatalf("expected one delivered message, got %d", len(delivered))
+	}
+	body := delivered[0]
+	for _, want := range []string{
+		"From: \"BitRiver Live\" <noreply@example.com>",
+		"To: <ada@example.com>",
+		"Subject: Your BitRiver Live password was changed",
+		"Content-Type: multipart/alternative; boundary=",
+		"Content-Type: text/plain; charset=utf-8",
+		"Content-Type: text/html; charset=utf-8",
+	} {
+		if !strings.Contains(body, want) {
+			t.Fatalf("expected %q in the message, got:\n%s", want, body)
+		}
+	}
+}
+
+func TestSMTPSenderClassifiesReplies(t *testing.T) {
+	server := newFakeSMTPServer(t, "421 try again later", "550 mailbox unavailable")
+	sender := newTestSender(t, server)
+	msg := mail.Message{To: "ada@example.com", Subject: "hello", Text: "hi"}
+
+	if err := sender.Send(context.Background(), msg); !mail.IsTransient(err) {
+		t.Fatalf("expected a 4xx reply to be transient, got %v", err)
+	}
+	if err := sender.Send(context.Background(), msg); err == nil || mail.IsTransient(err) {
+		t.Fatalf("expected a 5xx reply to be permanent, got %v", err)
+	}
+	if err := sender.Send(context.Background(), mail.Message{To: "ada@example.com", Subject: "a\r\nBcc: x@example.com", Text: "hi"}); err == nil || mail.IsTransient(err) {
+		t.Fatalf("expected header injection to be rejected, got %v", err)
+	}
+}
+
+func TestQueueRetriesTransientSMTPFailures(t *testing.T) {
+	server := newFakeSMTPServer(t, "451 greylisted", "421 busy")
+	recorder := metrics.New()
+	queue := mail.NewQueue(mail.QueueConfig{
+		Sender:      newTestSender(t, server),
+		BaseBackoff: 10 * time.Millisecond,
+		MaxBackoff:  20 * time.Millisecond,
+		Metrics:     recorder,
+	})
+	defer queue.Shutdown(context.Background())
+
+	if err := queue.Enqueue(mail.Message{To: "ada@example.com", Subject: "hello", Text: "hi"}); err != nil {
+		t.Fatalf("Enqueue: %v", err)
+	}
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		attempts, delivered := server.snapshot()
+		if len(delivered) == 1 {
+			if attempts != 3 {
+				t.Fatalf("expected delivery on the third attempt, got %d", attempts)
+			}
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("expected the message delivered after retries, got %d attempts", attempts)
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	waitForMailCount(t, recorder, "sent", 1)
+	if counts := recorder.MailCounts(); counts["retried"] != 2 || counts["failed"] != 0 {
+		t.Fatalf("expected two retries and no failures, got %v", counts)
+	}
+}
+
+func waitForMailCount(t *testing.T, recorder *metrics.Recorder, result string, want uint64) {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for recorder.MailCounts()[result] < want {
+		if time.Now().After(deadline) {
+			t.Fatalf("expected %d %s mail, got %v", want, result, recorder.MailCounts())
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+}
@@ -0,0 +1,109 @@
+package mail
+
+import (
+	"bytes"
+	"embed"
+	"fmt"
+	htmltemplate "html/template"
+	"strings"
+	texttemplate "text/template"
+	"time"
+)
+
+// Template names in the catalog.
+const (
+	TemplatePasswordChanged = "password_changed"
+	TemplateNewLogin        = "new_login"
+	TemplateVerifyEmail     = "verify_email"
+	TemplateLiveDigest      = "live_digest"
+)
+
+// PasswordChangedData fills TemplatePasswordChanged.
+type PasswordChangedData struct {
+	DisplayName string
+	ChangedAt   time.Time
+	// IPAddress is the address the change came from, if known.
+	IPAddress string
+	// AccountURL is where the user can secure their account.
+	AccountURL string
+}
+
+// NewLoginData fills TemplateNewLogin.
+type NewLoginData struct {
+	DisplayName string
+	LoginAt     time.Time
+	IPAddress   string
+	UserAgent   string
+	AccountURL  string
+}
+
+// VerifyEmailData fills TemplateVerifyEmail.
+type VerifyEmailData struct {
+	DisplayName string
+	VerifyURL   string
+	ExpiresAt   time.Time
+}
+
+// LiveDigestChannel is one followed channel that went live.
+type LiveDigestChannel struct {
+	Title       string
+	StreamTitle string
+	URL         string
+}
+
+// LiveDigestData fills TemplateLiveDigest. Channels must not be empty.
+type LiveDigestData struct {
+	DisplayName    string
+	Channels       []LiveDigestChannel
+	PreferencesURL string
+}
+
+//go:embed templates/*.tmpl
+var templateFS embed.FS
+
+type catalogEntry struct {
+	text *texttemplate.Template
+	html *htmltemplate.Template
+}
+
+var catalog = loadCatalog()
+
+func loadCatalog() map[string]catalogEntry {
+	entries := make(map[string]catalogEntry)
+	for _, name := range []string{TemplatePasswordChanged, TemplateNewLogin, TemplateVerifyEmail, TemplateLiveDigest} {
+		text := texttemplate.Must(texttemplate.New(name).ParseFS(templateFS, "templates/"+name+".txt.tmpl"))
+		// The HTML set shares the subject with the text set for its <title>;
+		// html/template escapes it for that context.
+		html := htmltemplate.Must(htmltemplate.New(name).ParseFS(templateFS, "templates/layout.html.tmpl", "templates/"+name+".html.tmpl"))
+		subject := text.Lookup("subject")
+		html = htmltemplate.Must(html.AddParseTree("subject", subject.Tree.Copy()))
+		entries[name] = catalogEntry{text: text, html: html}
+	}
+	return entries
+}
+
+// Render builds the message named by template for recipient to. data must be
+// the template's data type, such as PasswordChangedData. Values are escaped
+// in the HTML body and left as they are in the subject and text body.
+func Render(template, to string, data any) (Message, error) {
+	entry, ok := catalog[template]
+	if !ok {
+		return Message{}, fmt.Errorf("unknown mail template %q", template)
+	}
+	var subject, text, html bytes.Buffer
+	if err := entry.text.ExecuteTemplate(&subject, "subject", data); err != nil {
+		return Message{}, fmt.Errorf("render %s subject: %w", template, err)
+	}
+	if err := entry.text.ExecuteTemplate(&text, "text", data); err != nil {
+		return Message{}, fmt.Errorf("render %s text: %w", template, err)
+	}
+	if err := entry.html.ExecuteTemplate(&html, "layout", data); err != nil {
+		return Message{}, fmt.Errorf("render %s html: %w", template, err)
+	}
+	return Message{
+		To:      to,
+		Subject: strings.Join(strings.Fields(subject.String()), " "),
+		Text:    strings.TrimSpace(text.String()) + "\n",
+		HTML:    html.String(),
+	}, nil
+}
@@ -0,0 +1,74 @@
+package mail_test
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/mail"
+)
+
+func TestRenderEscapesHTMLOnly(t *testing.T) {
+	msg, err := mail.Render(mail.TemplatePasswordChanged, "viewer@example.com", mail.PasswordChangedData{
+		DisplayName: `<script>alert("x")</script>`,
+		ChangedAt:   time.Date(2025, 3, 1, 12, 30, 0, 0, time.UTC),
+		IPAddress:   "203.0.113.7",
+		AccountURL:  `javascript:alert(1)`,
+	})
+	if err != nil {
+		t.Fatalf("Render: %v", err)
+	}
+	if msg.To != "viewer@example.com" {
+		t.Fatalf("expected recipient to be kept, got %q", msg.To)
+	}
+	if msg.Subject != "Your BitRiver Live password was changed" {
+		t.Fatalf("unexpected subject %q", msg.Subject)
+	}
+	if strings.Contains(msg.HTML, "<script>") {
+		t.Fatalf("expected the display name escaped in HTML, got %s", msg.HTML)
+	}
+	if !strings.Contains(msg.HTML, "&lt;script&gt;") {
+		t.Fatalf("expected escaped markup in HTML, got %s", msg.HTML)
+	}
+	if strings.Contains(msg.HTML, `href="javascript:`) {
+		t.Fatalf("expected unsafe URLs filtered from HTML, got %s", msg.HTML)
+	}
+	if !strings.Contains(msg.Text, `Hi <script>alert("x")</script>,`) {
+		t.Fatalf("expected the text body left unescaped, got %s", msg.Text)
+	}
+	if !strings.Contains(msg.Text, "1 Mar 2025 at 12:30 UTC from 203.0.113.7") {
+		t.Fatalf("expected the change time and address in the text body, got %s", msg.Text)
+	}
+}
+
+func TestRenderCatalog(t *testing.T) {
+	now := time.Date(2025, 3, 1, 12, 30, 0, 0, time.UTC)
+	cases := map[string]any{
+		mail.TemplatePasswordChanged: mail.PasswordChangedData{DisplayName: "Ada", ChangedAt: now, AccountURL: "https://live.example.com/account"},
+		mail.TemplateNewLogin:        mail.NewLoginData{DisplayName: "Ada", LoginAt: now, IPAddress: "198.51.100.2", UserAgent: "Firefox", AccountURL: "https://live.example.com/account"},
+		mail.TemplateVerifyEmail:     mail.VerifyEmailData{DisplayName: "Ada", VerifyURL: "https://live.example.com/verify?token=abc", ExpiresAt: now.Add(24 * time.Hour)},
+		mail.TemplateLiveDigest: mail.LiveDigestData{
+			DisplayName:    "Ada",
+			Channels:       []mail.LiveDigestChannel{{Title: "Speedruns", StreamTitle: "Any% attempts", URL: "https://live.example.com/c/speedruns"}},
+			PreferencesURL: "https://live.example.com/settings/notifications",
+		},
+	}
+	for name, data := range cases {
+		msg, err := mail.Render(name, "ada@example.com", data)
+		if err != nil {
+			t.Fatalf("Render %s: %v", name, err)
+		}
+		if msg.Subject == "" || strings.ContainsAny(msg.Subject, "\r\n") {
+			t.Fatalf("%s: expected a single-line subject, got %q", name, msg.Subject)
+		}
+		if !strings.Contains(msg.Text, "Ada") || !strings.Contains(msg.HTML, "Ada") {
+			t.Fatalf("%s: expected both bodies to greet the user", name)
+		}
+		if !strings.Contains(msg.HTML, "<title>"+msg.Subject+"</title>") {
+			t.Fatalf("%s: expected the subject as the HTML title, got %s", name, msg.HTML)
+		}
+	}
+	if _, err := mail.Render("missing", "ada@example.com", nil); err == nil {
+		t.Fatal("expected unknown templates to be rejected")
+	}
+}
@@ -0,0 +1,167 @@
+package malware
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+)
+
+const (
+	defaultClamdTimeout   = 2 * time.Minute
+	defaultClamdChunkSize = 64 << 10
+	// maxClamdReply bounds how much of the daemon's reply is read.
+	maxClamdReply = 4 << 10
+)
+
+// ClamdConfig describes how to reach a ClamAV daemon.
+type ClamdConfig struct {
+	// Address is the daemon's TCP address, such as "clamav:3310".
+	Address string
+	// Timeout bounds a whole scan, from connecting to reading the verdict,
+	// when ctx has no earlier deadline. Zero uses two minutes.
+	Timeout time.Duration
+	// ChunkSize is how many bytes are sent per INSTREAM chunk. Zero uses
+	// 64 KiB. It must stay below the daemon's StreamMaxLength.
+	ChunkSize int
+}
+
+// ClamdScanner scans files with a ClamAV daemon, streaming them over TCP
+// with the INSTREAM command so the daemon needs no access to the server's
+// disk.
+type ClamdScanner struct {
+	address   string
+	timeout   time.Duration
+	chunkSize int
+	dialer    net.Dialer
+}
+
+// NewClamdScanner returns a scanner for the daemon at cfg.Address.
+func NewClamdScanner(cfg ClamdConfig) (*ClamdScanner, error) {
+	address := strings.TrimSpace(cfg.Address)
+	if address == "" {
+		return nil, errors.New("clamd address is required")
+	}
+	if _, _, err := net.SplitHostPort(address); err != nil {
+		return nil, fmt.Errorf("clamd address %q: %w", address, err)
+	}
+	timeout := cfg.Timeout
+	if timeout <= 0 {
+		timeout = defaultClamdTimeout
+	}
+	chunkSize := cfg.ChunkSize
+	if chunkSize <= 0 {
+		chunkSize = defaultClamdChunkSize
+	}
+	return &ClamdScanner{address: address, timeout: timeout, chunkSize: chunkSize}, nil
+}
+
+// Scan streams r to the daemon and parses its verdict.
+func (s *ClamdScanner) Scan(ctx context.Context, name string, r io.Reader) (Result, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	conn, err := s.dialer.DialContext(ctx, "tcp", s.address)
+	if err != nil {
+		return Result{}, fmt.Errorf("connect to clamd: %w", err)
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = conn.SetDeadline(deadline)
+	}
+	// Closing the connection unblocks a read or write when ctx is
+	// cancelled before its deadline.
+	stop := context.AfterFunc(ctx, func() { _ = conn.Close() })
+	defer stop()
+
+	result, err := s.instream(conn, r)
+	if err != nil {
+		if ctxErr := ctx.Err(); ctxErr != nil {
+			return Result{}, fmt.Errorf("scan %s: %w", name, ctxErr)
+		}
+		return Result{}, fmt.Errorf("scan %s: %w", name, err)
+	}
+	return result, nil
+}
+
+// instream runs the INSTREAM exchange: the command, length-prefixed chunks
+// of the file, a zero-length chunk, then one reply.
+func (s *ClamdScanner) instream(conn net.Conn, r io.Reader) (Result, error) {
+	writer := bufio.NewWriterSize(conn, s.chunkSize+4)
+	if _, err := writer.WriteString("zINSTREAM\x00"); err != nil {
+		return Result{}, fmt.Errorf("send command: %w", err)
+	}
+	buf := make([]byte, s.chunkSize)
+	var size [4]byte
+	for {
+		n, readErr := io.ReadFull(r, buf)
+		if n > 0 {
+			binary.BigEndian.PutUint32(size[:], uint32(n))
+			if _, err := writer.Write(size[:]); err != nil {
+				return Result{}, fmt.Errorf("send chunk: %w", err)
+			}
+			if _, err := writer.Write(buf[:n]); err != nil {
+				return Result{}, fmt.Errorf("send chunk: %w", err)
+			}
+		}
+		if errors.Is(readErr, io.EOF) || errors.Is(readErr, io.ErrUnexpectedEOF) {
+			break
+		}
+		if readErr != nil {
+			return Result{}, fmt.Errorf("read file: %w", readErr)
+		}
+	}
+	binary.BigEndian.PutUint32(size[:], 0)
+	if _, err := writer.Write(size[:]); err != nil {
+		return Result{}, fmt.Errorf("end stream: %w", err)
+	}
+	if err := writer.Flush(); err != nil {
+		// The daemon closes the connection early when the file passes
+		// its StreamMaxLength; its reply explains why.
+		if reply, readErr := readClamdReply(conn); readErr == nil && reply != "" {
+			return parseClamdReply(reply)
+		}
+		return Result{}, fmt.Errorf("send file: %w", err)
+	}
+	reply, err := readClamdReply(conn)
+	if err != nil {
+		return Result{}, err
+	}
+	return parseClamdReply(reply)
+}
+
+func readClamdReply(conn net.Conn) (string, error) {
+	reply, err := io.ReadAll(io.LimitReader(conn, maxClamdReply))
+	if err != nil && len(reply) == 0 {
+		return "", fmt.Errorf("read reply: %w", err)
+	}
+	reply = bytes.TrimRight(reply, "\x00\n")
+	if len(reply) == 0 {
+		return "", errors.New("read reply: empty reply")
+	}
+	return string(reply), nil
+}
+
+// parseClamdReply reads replies of the form "stream: OK",
+// "stream: <signature> FOUND", and "<message> ERROR".
+func parseClamdReply(reply string) (Result, error) {
+	status := strings.TrimSpace(reply)
+	if _, rest, ok := strings.Cut(status, ": "); ok {
+		status = strings.TrimSpace(rest)
+	}
+	switch {
+	case status == "OK":
+		return Result{Verdict: VerdictClean}, nil
+	case strings.HasSuffix(status, " FOUND"):
+		return Result{Verdict: VerdictInfected, Signature: strings.TrimSpace(strings.TrimSuffix(status, " FOUND"))}, nil
+	case strings.HasSuffix(status, " ERROR"):
+		return Result{}, fmt.Errorf("clamd: %s", strings.TrimSpace(strings.TrimSuffix(status, " ERROR")))
+	default:
+		return Result{}, fmt.Errorf("clamd: unexpected reply %q", reply)
+	}
+}
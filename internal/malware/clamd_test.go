@@ -0,0 +1,150 @@
+package malware
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/binary"
+	"io"
+	"net"
+	"strings"
+	"testing"
+	"time"
+)
+
+// fakeClamd accepts one INSTREAM session per connection, records the bytes
+// it received, and answers with reply(received).
+func fakeClamd(t *testing.T, reply func(received []byte) string) (string, <-chan []byte) {
+	t.Helper()
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	received := make(chan []byte, 1)
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		reader := bufio.NewReader(conn)
+		command, err := reader.ReadString('\x00')
+		if err != nil || command != "zINSTREAM\x00" {
+			conn.Write([]byte("UNKNOWN COMMAND\x00"))
+			return
+		}
+		var body bytes.Buffer
+		var size [4]byte
+		for {
+			if _, err := io.ReadFull(reader, size[:]); err != nil {
+				return
+			}
+			n := binary.BigEndian.Uint32(size[:])
+			if n == 0 {
+				break
+			}
+			if _, err := io.CopyN(&body, reader, int64(n)); err != nil {
+				return
+			}
+		}
+		received <- body.Bytes()
+		conn.Write([]byte(reply(body.Bytes()) + "\x00"))
+	}()
+	return listener.Addr().String(), received
+}
+
+func TestClamdScannerVerdicts(t *testing.T) {
+	tests := []struct {
+		name      string
+		reply     string
+		want      Result
+		wantError string
+	}{
+		{name: "clean", reply: "stream: OK", want: Result{Verdict: VerdictClean}},
+		{name: "infected", reply: "stream: Eicar-Test-Signature FOUND", want: Result{Verdict: VerdictInfected, Signature: "Eicar-Test-Signature"}},
+		{name: "daemon error", reply: "INSTREAM size limit exceeded. ERROR", wantError: "size limit exceeded"},
+		{name: "unexpected reply", reply: "stream: MAYBE", wantError: "unexpected reply"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			address, received := fakeClamd(t, func([]byte) string { return tt.reply })
+			scanner, err := NewClamdScanner(ClamdConfig{Address: address, ChunkSize: 3})
+			if err != nil {
+				t.Fatalf("NewClamdScanner: %v", err)
+			}
+			payload := "upload payload spanning several chunks"
+			result, err := scanner.Scan(context.Background(), "video.mp4", strings.NewReader(payload))
+			if got := string(<-received); got != payload {
+				t.Fatalf("daemon received %q, want %q", got, payload)
+			}
+			if tt.wantError != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantError) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantError, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Scan: %v", err)
+			}
+			if result != tt.want {
+				t.Fatalf("result = %+v, want %+v", result, tt.want)
+			}
+		})
+	}
+}
+
+func TestClamdScannerUnreachable(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	address := listener.Addr().String()
+	listener.Close()
+
+	scanner, err := NewClamdScanner(ClamdConfig{Address: address})
+	if err != nil {
+		t.Fatalf("NewClamdScanner: %v", err)
+	}
+	if _, err := scanner.Scan(context.Background(), "video.mp4", strings.NewReader("data")); err == nil {
+		t.Fatal("expected an error when clamd is unreachable")
+	}
+}
+
+func TestClamdScannerHonoursTimeout(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		// Drain the upload but never answer.
+		io.Copy(io.Discard, conn)
+	}()
+
+	scanner, err := NewClamdScanner(ClamdConfig{Address: listener.Addr().String(), Timeout: 100 * time.Millisecond})
+	if err != nil {
+		t.Fatalf("NewClamdScanner: %v", err)
+	}
+	started := time.Now()
+	_, err = scanner.Scan(context.Background(), "video.mp4", strings.NewReader("data"))
+	if err == nil {
+		t.Fatal("expected a timeout error")
+	}
+	if elapsed := time.Since(started); elapsed > 5*time.Second {
+		t.Fatalf("scan took %s despite a 100ms timeout", elapsed)
+	}
+}
+
+func TestNewClamdScannerRejectsBadAddress(t *testing.T) {
+	for _, address := range []string{"", "   ", "clamav"} {
+		if _, err := NewClamdScanner(ClamdConfig{Address: address}); err == nil {
+			t.Fatalf("expected an error for address %q", address)
+		}
+	}
+}
@@ -0,0 +1,8 @@
+// Package malware scans uploaded files before the platform processes them.
+//
+// A Scanner reads a file and reports whether it is clean or infected.
+// ClamdScanner streams the file to a ClamAV daemon over TCP; NoopScanner,
+// used when no daemon is configured, reports every file clean without
+// reading it. What happens to an infected file, or to one that could not be
+// scanned, is decided by the caller.
+package malware
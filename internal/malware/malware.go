@@ -0,0 +1,44 @@
+package malware
+
+import (
+	"context"
+	"io"
+)
+
+// Verdict is a scanner's conclusion about a file.
+type Verdict string
+
+const (
+	// VerdictClean means the scanner found nothing.
+	VerdictClean Verdict = "clean"
+	// VerdictInfected means the scanner matched a signature.
+	VerdictInfected Verdict = "infected"
+)
+
+// Result is a scanner's verdict on one file. Signature names what was found
+// in an infected file.
+type Result struct {
+	Verdict   Verdict
+	Signature string
+}
+
+// Infected reports whether the scanner matched a signature.
+func (r Result) Infected() bool {
+	return r.Verdict == VerdictInfected
+}
+
+// Scanner inspects a file. Scan returns an error, and no verdict, when the
+// file could not be scanned: the scanner was unreachable, ctx ended, or the
+// scanner refused the file.
+type Scanner interface {
+	Scan(ctx context.Context, name string, r io.Reader) (Result, error)
+}
+
+// NoopScanner reports every file clean without reading it. It is the
+// scanner used when none is configured.
+type NoopScanner struct{}
+
+// Scan returns a clean verdict.
+func (NoopScanner) Scan(context.Context, string, io.Reader) (Result, error) {
+	return Result{Verdict: VerdictClean}, nil
+}
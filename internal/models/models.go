@@ -140,13 +140,51 @@ func formatMinorUnits(units int64) string {
 }
 
 type User struct {
-	ID           string    `json:"id"`
-	DisplayName  string    `json:"displayName"`
+	ID          string `json:"id"`
+	DisplayName string `json:"displayName"`
+	// Username is the unique handle used in mentions and profile URLs. It
+	// keeps the case it was chosen with but is compared without it.
+	Username     string    `json:"username"`
 	Email        string    `json:"email"`
 	Roles        []string  `json:"roles"`
 	PasswordHash string    `json:"passwordHash,omitempty"`
 	SelfSignup   bool      `json:"selfSignup"`
 	CreatedAt    time.Time `json:"createdAt"`
+	// UsernameChangedAt is when the user last chose their username.
+	// UsernameGrace marks a username that was assigned automatically, which
+	// the user may replace once without waiting for the change cooldown.
+	UsernameChangedAt *time.Time `json:"usernameChangedAt,omitempty"`
+	UsernameGrace     bool       `json:"usernameGrace,omitempty"`
+	// BirthDate is set once by the user to unlock mature channels, and
+	// AgeConfirmedAt records when they did so.
+	BirthDate      *time.Time `json:"birthDate,omitempty"`
+	AgeConfirmedAt *time.Time `json:"ageConfirmedAt,omitempty"`
+	// DeactivatedAt is set when the account has been deprovisioned. A
+	// deactivated user keeps their data but cannot sign in.
+	DeactivatedAt *time.Time `json:"deactivatedAt,omitempty"`
+	// MaxConcurrentStreams overrides the platform limit on how many of the
+	// user's channels may stream at once. Nil uses the platform default and
+	// zero lifts the limit.
+	MaxConcurrentStreams *int `json:"maxConcurrentStreams,omitempty"`
+}
+
+// MatureContentMinimumAge is the age a viewer must have confirmed before
+// they may watch channels flagged as mature content.
+const MatureContentMinimumAge = 18
+
+// AgeAt returns the user's age in whole years at the given time, and false
+// when they have not confirmed a birth date.
+func (u User) AgeAt(now time.Time) (int, bool) {
+	if u.BirthDate == nil {
+		return 0, false
+	}
+	born := u.BirthDate.UTC()
+	now = now.UTC()
+	age := now.Year() - born.Year()
+	if now.Month() < born.Month() || (now.Month() == born.Month() && now.Day() < born.Day()) {
+		age--
+	}
+	return age, true
 }
 
 // HasRole reports whether the user has the provided role, ignoring case.
@@ -159,26 +197,375 @@ func (u User) HasRole(role string) bool {
 	return false
 }
 
+// Deactivated reports whether the account has been deprovisioned.
+func (u User) Deactivated() bool {
+	return u.DeactivatedAt != nil
+}
+
+// APIToken is a personal access token that lets automation act on behalf of a
+// user. Only a hash of the secret is persisted; the secret itself is returned
+// once at creation time.
+type APIToken struct {
+	ID        string     `json:"id"`
+	UserID    string     `json:"userId"`
+	Name      string     `json:"name"`
+	Scopes    []string   `json:"scopes"`
+	TokenHash string     `json:"tokenHash"`
+	CreatedAt time.Time  `json:"createdAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// HasScope reports whether the token grants the provided scope, ignoring case.
+func (t APIToken) HasScope(scope string) bool {
+	for _, existing := range t.Scopes {
+		if strings.EqualFold(existing, scope) {
+			return true
+		}
+	}
+	return false
+}
+
+// Notification categories a user can tune independently.
+const (
+	NotificationCategoryFollowedLive = "followedLive"
+	NotificationCategoryMentions     = "mentions"
+	NotificationCategoryModeration   = "moderation"
+	NotificationCategoryAccount      = "account"
+)
+
+// Notification delivery methods.
+const (
+	NotificationDeliveryInApp = "inApp"
+	NotificationDeliveryEmail = "email"
+)
+
+// NotificationDelivery toggles the delivery methods for one category.
+type NotificationDelivery struct {
+	InApp bool `json:"inApp"`
+	Email bool `json:"email"`
+}
+
+// NotificationPreferences records how a user wants to hear about each
+// notification category. Users who never changed them get
+// DefaultNotificationPreferences.
+type NotificationPreferences struct {
+	UserID       string               `json:"userId"`
+	FollowedLive NotificationDelivery `json:"followedLive"`
+	Mentions     NotificationDelivery `json:"mentions"`
+	Moderation   NotificationDelivery `json:"moderation"`
+	Account      NotificationDelivery `json:"account"`
+	UpdatedAt    *time.Time           `json:"updatedAt,omitempty"`
+}
+
+// DefaultNotificationPreferences enables in-app notifications for every
+// category and email only for moderation and account notices, which users
+// should not miss.
+func DefaultNotificationPreferences(userID string) NotificationPreferences {
+	return NotificationPreferences{
+		UserID:       userID,
+		FollowedLive: NotificationDelivery{InApp: true},
+		Mentions:     NotificationDelivery{InApp: true},
+		Moderation:   NotificationDelivery{InApp: true, Email: true},
+		Account:      NotificationDelivery{InApp: true, Email: true},
+	}
+}
+
+// Category returns the delivery settings for category, reporting false for
+// unknown categories.
+func (p NotificationPreferences) Category(category string) (NotificationDelivery, bool) {
+	switch category {
+	case NotificationCategoryFollowedLive:
+		return p.FollowedLive, true
+	case NotificationCategoryMentions:
+		return p.Mentions, true
+	case NotificationCategoryModeration:
+		return p.Moderation, true
+	case NotificationCategoryAccount:
+		return p.Account, true
+	default:
+		return NotificationDelivery{}, false
+	}
+}
+
+// Allows reports whether a notification in category may be delivered by the
+// given method. Notification producers must check it before creating an
+// in-app notification or sending an email.
+func (p NotificationPreferences) Allows(category, delivery string) bool {
+	settings, ok := p.Category(category)
+	if !ok {
+		return false
+	}
+	switch delivery {
+	case NotificationDeliveryInApp:
+		return settings.InApp
+	case NotificationDeliveryEmail:
+		return settings.Email
+	default:
+		return false
+	}
+}
+
+// Notification is an in-app notice for UserID. Category is one of the
+// NotificationCategory values the user's preferences are checked against,
+// and Kind names what happened, such as a SecurityEvent kind.
+type Notification struct {
+	ID        string            `json:"id"`
+	UserID    string            `json:"userId"`
+	Category  string            `json:"category"`
+	Kind      string            `json:"kind"`
+	ChannelID string            `json:"channelId,omitempty"`
+	Message   string            `json:"message"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+// Playback quality preferences a viewer can pick as their default rendition.
+// Auto leaves the choice to the player's adaptive bitrate logic.
+const (
+	PlaybackQualityAuto   = "auto"
+	PlaybackQualitySource = "source"
+	PlaybackQualityHigh   = "high"
+	PlaybackQualityMedium = "medium"
+	PlaybackQualityLow    = "low"
+)
+
+// playbackQualityHeights caps the rendition height for each fixed quality.
+var playbackQualityHeights = map[string]int{
+	PlaybackQualityHigh:   1080,
+	PlaybackQualityMedium: 720,
+	PlaybackQualityLow:    480,
+}
+
+// NormalizePlaybackQuality lowercases and trims value, reporting false when
+// it is not one of the PlaybackQuality constants.
+func NormalizePlaybackQuality(value string) (string, bool) {
+	quality := strings.ToLower(strings.TrimSpace(value))
+	switch quality {
+	case PlaybackQualityAuto, PlaybackQualitySource:
+		return quality, true
+	}
+	if _, ok := playbackQualityHeights[quality]; ok {
+		return quality, true
+	}
+	return "", false
+}
+
+// PlaybackQualityMaxHeight returns the tallest rendition height quality
+// allows. Auto and source report false as they do not cap the height.
+func PlaybackQualityMaxHeight(quality string) (int, bool) {
+	height, ok := playbackQualityHeights[quality]
+	return height, ok
+}
+
+// PlaybackPreferences records how a user wants the player to start: the
+// default rendition, whether to favour a low-latency protocol, and whether to
+// autoplay muted. Users who never saved preferences get
+// DefaultPlaybackPreferences.
+type PlaybackPreferences struct {
+	UserID           string     `json:"userId"`
+	DefaultQuality   string     `json:"defaultQuality"`
+	PreferLowLatency bool       `json:"preferLowLatency"`
+	MutedAutoplay    bool       `json:"mutedAutoplay"`
+	UpdatedAt        *time.Time `json:"updatedAt,omitempty"`
+}
+
+// DefaultPlaybackPreferences leaves rendition selection to the player.
+func DefaultPlaybackPreferences(userID string) PlaybackPreferences {
+	return PlaybackPreferences{UserID: userID, DefaultQuality: PlaybackQualityAuto}
+}
+
 type OAuthAccount struct {
-	Provider    string    `json:"provider"`
-	Subject     string    `json:"subject"`
-	UserID      string    `json:"userId"`
-	Email       string    `json:"email"`
-	DisplayName string    `json:"displayName"`
-	LinkedAt    time.Time `json:"linkedAt"`
+	Provider     string    `json:"provider"`
+	Subject      string    `json:"subject"`
+	SubjectIndex string    `json:"subjectIndex,omitempty"`
+	UserID       string    `json:"userId"`
+	Email        string    `json:"email"`
+	DisplayName  string    `json:"displayName"`
+	LinkedAt     time.Time `json:"linkedAt"`
 }
 
+// Channel describes a creator's channel. StreamKey carries the plaintext key
+// only on the values returned when a channel is created or its key rotated;
+// repositories persist the StreamKeyHash digest and a StreamKeyHint for
+// display instead. PlaybackRestriction limits live playback to followers or
+// subscribers; PlaybackPreviews lets everyone else watch anonymously anyway.
+// RecordingPolicy decides what happens to a stream's recording when it ends.
+// MatureContent restricts playback to viewers who confirmed they are at least
+// MatureContentMinimumAge. TranscodeLimits is an admin-set override of the
+// platform transcode caps for this channel.
 type Channel struct {
-	ID               string    `json:"id"`
-	OwnerID          string    `json:"ownerId"`
-	StreamKey        string    `json:"streamKey"`
-	Title            string    `json:"title"`
-	Category         string    `json:"category,omitempty"`
-	Tags             []string  `json:"tags"`
-	LiveState        string    `json:"liveState"`
-	CurrentSessionID *string   `json:"currentSessionId,omitempty"`
-	CreatedAt        time.Time `json:"createdAt"`
-	UpdatedAt        time.Time `json:"updatedAt"`
+	ID      string `json:"id"`
+	OwnerID string `json:"ownerId"`
+	// Slug is the unique, lowercase, URL-safe name used in channel URLs.
+	// SlugChangedAt is when the owner last chose it; it is nil while the
+	// channel keeps the slug derived from its title at creation.
+	Slug             string     `json:"slug"`
+	SlugChangedAt    *time.Time `json:"slugChangedAt,omitempty"`
+	StreamKey        string     `json:"streamKey,omitempty"`
+	StreamKeyHash    string     `json:"streamKeyHash,omitempty"`
+	StreamKeyHint    string     `json:"streamKeyHint,omitempty"`
+	Title            string     `json:"title"`
+	Category         string     `json:"category,omitempty"`
+	Tags             []string   `json:"tags"`
+	LiveState        string     `json:"liveState"`
+	CurrentSessionID *string    `json:"currentSessionId,omitempty"`
+	CreatedAt        time.Time  `json:"createdAt"`
+	UpdatedAt        time.Time  `json:"updatedAt"`
+	// PlaybackRestriction is empty for public channels.
+	PlaybackRestriction string `json:"playbackRestriction,omitempty"`
+	PlaybackPreviews    bool   `json:"playbackPreviews,omitempty"`
+	// RecordingPolicy is empty on channels created before the setting
+	// existed, which behave as RecordingPolicyManual.
+	RecordingPolicy string           `json:"recordingPolicy,omitempty"`
+	MatureContent   bool             `json:"matureContent,omitempty"`
+	TranscodeLimits *TranscodeLimits `json:"transcodeLimits,omitempty"`
+	// Language is the BCP-47 tag, one of SupportedChannelLanguages, the
+	// channel streams in. It is empty until the creator sets it.
+	// ContentWarnings are ContentWarning values, sorted; nil when the channel
+	// has none.
+	Language        string   `json:"language,omitempty"`
+	ContentWarnings []string `json:"contentWarnings,omitempty"`
+	// ChatMaxCapsPercent lowercases chat messages whose letters are more than
+	// this percentage capitals; zero turns the rule off. ChatRestrictLinks
+	// accepts chat messages with links only from followers, subscribers, and
+	// moderators. ChatSubscribersOnly accepts chat messages only from
+	// subscribers and moderators. ChatWelcomeMessage is shown by clients to
+	// each viewer joining the chat; empty shows none.
+	ChatMaxCapsPercent  int    `json:"chatMaxCapsPercent,omitempty"`
+	ChatRestrictLinks   bool   `json:"chatRestrictLinks,omitempty"`
+	ChatSubscribersOnly bool   `json:"chatSubscribersOnly,omitempty"`
+	ChatWelcomeMessage  string `json:"chatWelcomeMessage,omitempty"`
+
+	// StartingSince is when LiveState last became "starting". It is nil in
+	// every other state.
+	StartingSince *time.Time `json:"startingSince,omitempty"`
+}
+
+// ChannelSlugRedirect keeps a slug a channel used before pointing at it, so
+// links made with the old slug keep working after a rename.
+type ChannelSlugRedirect struct {
+	Slug      string    `json:"slug"`
+	ChannelID string    `json:"channelId"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// Channel.PlaybackRestriction values naming the viewers allowed to watch a
+// restricted channel's live stream.
+const (
+	PlaybackRestrictionFollowers   = "followers"
+	PlaybackRestrictionSubscribers = "subscribers"
+)
+
+// Channel.RecordingPolicy values. Manual leaves recordings unpublished until
+// the creator publishes them, auto-publish publishes them as soon as the
+// stream stops, and discard records nothing.
+const (
+	RecordingPolicyManual      = "manual"
+	RecordingPolicyAutoPublish = "auto-publish"
+	RecordingPolicyDiscard     = "discard"
+)
+
+// SupportedChannelLanguages lists the BCP-47 tags a channel's Language may
+// take, in their canonical case.
+var SupportedChannelLanguages = []string{
+	"ar", "cs", "da", "de", "el", "en", "en-GB", "en-US", "es", "es-419",
+	"fi", "fr", "fr-CA", "he", "hi", "hu", "id", "it", "ja", "ko", "nl",
+	"no", "pl", "pt", "pt-BR", "ro", "ru", "sv", "th", "tr", "uk", "vi",
+	"zh", "zh-Hans", "zh-Hant",
+}
+
+// Channel.ContentWarnings values.
+const (
+	ContentWarningViolence       = "violence"
+	ContentWarningGambling       = "gambling"
+	ContentWarningStrongLanguage = "strong_language"
+	ContentWarningSexualThemes   = "sexual_themes"
+	ContentWarningDrugs          = "drugs"
+	ContentWarningFlashingLights = "flashing_lights"
+)
+
+// ContentWarnings lists every ContentWarning value.
+var ContentWarnings = []string{
+	ContentWarningViolence,
+	ContentWarningGambling,
+	ContentWarningStrongLanguage,
+	ContentWarningSexualThemes,
+	ContentWarningDrugs,
+	ContentWarningFlashingLights,
+}
+
+// TranscodeLimits caps what a channel may push through the transcoder.
+// MaxHeight bounds each rendition's shorter side in pixels (1080 admits 1080p
+// landscape and portrait), MaxBitrate the summed
+// video bitrate of the ladder in kbps, and MaxRenditions the number of
+// renditions. A zero field leaves that dimension unlimited.
+type TranscodeLimits struct {
+	MaxHeight     int `json:"maxHeight,omitempty"`
+	MaxBitrate    int `json:"maxBitrate,omitempty"`
+	MaxRenditions int `json:"maxRenditions,omitempty"`
+}
+
+// IsZero reports whether no limit is set.
+func (l TranscodeLimits) IsZero() bool {
+	return l.MaxHeight <= 0 && l.MaxBitrate <= 0 && l.MaxRenditions <= 0
+}
+
+// WithOverride returns l with every positive field of override applied, so a
+// channel override beats the platform default one field at a time.
+func (l TranscodeLimits) WithOverride(override *TranscodeLimits) TranscodeLimits {
+	if override == nil {
+		return l
+	}
+	if override.MaxHeight > 0 {
+		l.MaxHeight = override.MaxHeight
+	}
+	if override.MaxBitrate > 0 {
+		l.MaxBitrate = override.MaxBitrate
+	}
+	if override.MaxRenditions > 0 {
+		l.MaxRenditions = override.MaxRenditions
+	}
+	return l
+}
+
+// MinChannelGrantDuration is the shortest lifetime a time-limited moderator
+// or editor grant may be given.
+const MinChannelGrantDuration = 5 * time.Minute
+
+// ChannelEditor grants a user with the editor role permission to manage a
+// single channel's recordings and uploads. A grant with ExpiresAt set stops
+// applying at that instant, whether or not it has been swept yet.
+type ChannelEditor struct {
+	ChannelID string     `json:"channelId"`
+	UserID    string     `json:"userId"`
+	GrantedBy string     `json:"grantedBy,omitempty"`
+	GrantedAt time.Time  `json:"grantedAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// ActiveAt reports whether the grant applies at now.
+func (e ChannelEditor) ActiveAt(now time.Time) bool {
+	return grantActiveAt(e.ExpiresAt, now)
+}
+
+// ChannelModerator grants a user permission to moderate a single channel's
+// chat, for example a guest host. A grant with ExpiresAt set stops applying
+// at that instant, whether or not it has been swept yet.
+type ChannelModerator struct {
+	ChannelID string     `json:"channelId"`
+	UserID    string     `json:"userId"`
+	GrantedBy string     `json:"grantedBy,omitempty"`
+	GrantedAt time.Time  `json:"grantedAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+}
+
+// ActiveAt reports whether the grant applies at now.
+func (m ChannelModerator) ActiveAt(now time.Time) bool {
+	return grantActiveAt(m.ExpiresAt, now)
+}
+
+func grantActiveAt(expiresAt *time.Time, now time.Time) bool {
+	return expiresAt == nil || now.Before(*expiresAt)
 }
 
 type StreamSession struct {
@@ -193,6 +580,113 @@ type StreamSession struct {
 	IngestEndpoints    []string            `json:"ingestEndpoints,omitempty"`
 	IngestJobIDs       []string            `json:"ingestJobIds,omitempty"`
 	RenditionManifests []RenditionManifest `json:"renditionManifests,omitempty"`
+	// PreviewURL is the transcoder's public preview frame for the session,
+	// refreshed while it is live.
+	PreviewURL string `json:"previewUrl,omitempty"`
+	// PlaybackEndpoints lists every protocol the origin serves the session
+	// over, most preferred first. PlaybackURL stays set to the preferred
+	// HLS-family endpoint for players that only understand one URL.
+	PlaybackEndpoints []PlaybackEndpoint `json:"playbackEndpoints,omitempty"`
+	// Settings is nil for sessions started before settings were recorded.
+	Settings *StreamSettings `json:"settings,omitempty"`
+	// ReceivingMedia reports whether the ingest server last said media was
+	// arriving. MediaConfirmedAt is when it first did for this session.
+	ReceivingMedia   bool       `json:"receivingMedia"`
+	MediaConfirmedAt *time.Time `json:"mediaConfirmedAt,omitempty"`
+	// InterruptedAt is when media stopped after having arrived, cleared
+	// again when the encoder reconnects.
+	InterruptedAt *time.Time `json:"interruptedAt,omitempty"`
+	// PublishSource is the address the ingest server reported the encoder
+	// publishing from. Publishes from any other address are refused while
+	// the session is live. Empty until ingest reports one.
+	PublishSource string `json:"publishSource,omitempty"`
+}
+
+// SecurityEvent records something suspicious on a channel: its stream key
+// being misused, or an infected file being uploaded to it. SourceIP is the
+// address involved and SessionID the live session at the time, when there
+// was one.
+type SecurityEvent struct {
+	ID        string    `json:"id"`
+	ChannelID string    `json:"channelId"`
+	Kind      string    `json:"kind"`
+	SourceIP  string    `json:"sourceIp,omitempty"`
+	SessionID string    `json:"sessionId,omitempty"`
+	Detail    string    `json:"detail,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// SecurityEvent.Kind values.
+const (
+	// SecurityEventPublishRejected is a publish refused because the key was
+	// already live from another address.
+	SecurityEventPublishRejected = "publish_rejected"
+	// SecurityEventKeySourcesSpread flags a key used from more distinct
+	// networks in a window than one broadcaster plausibly would.
+	SecurityEventKeySourcesSpread = "key_sources_spread"
+	// SecurityEventKeyRevoked is the owner rotating the key and ending the
+	// stream in one step.
+	SecurityEventKeyRevoked = "key_revoked"
+	// SecurityEventUploadInfected is an uploaded file the malware scanner
+	// flagged; the file is quarantined and the upload failed.
+	SecurityEventUploadInfected = "upload_infected"
+)
+
+// StreamSettings records how a session was configured when it started. It
+// is written once, so later channel changes do not alter how a past session
+// is reported.
+type StreamSettings struct {
+	// RequestedRenditions are the rendition names asked for at start.
+	RequestedRenditions []string `json:"requestedRenditions"`
+	// Ladder is the rendition ladder the ingest backend actually built.
+	Ladder      []LadderRendition `json:"ladder"`
+	LatencyMode string            `json:"latencyMode,omitempty"`
+	// Language is the channel's language when the session started, empty
+	// when none was set.
+	Language string `json:"language,omitempty"`
+}
+
+// LadderRendition is one rung of a session's effective rendition ladder.
+type LadderRendition struct {
+	Name    string `json:"name"`
+	Bitrate int    `json:"bitrate,omitempty"`
+}
+
+// Playback protocols a live session can be served over.
+const (
+	PlaybackProtocolWebRTC = "webrtc"
+	PlaybackProtocolLLHLS  = "llhls"
+	PlaybackProtocolHLS    = "hls"
+)
+
+// PlaybackEndpoint is one way to play a live session. Players try endpoints
+// in ascending Priority order, so 1 is attempted first.
+type PlaybackEndpoint struct {
+	Protocol string `json:"protocol"`
+	URL      string `json:"url"`
+	Priority int    `json:"priority"`
+}
+
+// IsHLS reports whether the endpoint is served as HLS or LL-HLS.
+func (e PlaybackEndpoint) IsHLS() bool {
+	return e.Protocol == PlaybackProtocolHLS || e.Protocol == PlaybackProtocolLLHLS
+}
+
+// Latency modes reported for live sessions.
+const (
+	LatencyModeLow      = "low-latency"
+	LatencyModeUltraLow = "ultra-low"
+)
+
+// LatencyModeForPlaybackURL infers a session's latency mode from its
+// playback URL: WebRTC URLs are ultra-low latency and everything else is
+// served as LL-HLS.
+func LatencyModeForPlaybackURL(playbackURL string) string {
+	url := strings.ToLower(playbackURL)
+	if strings.HasPrefix(url, "webrtc") || strings.HasPrefix(url, "wss") {
+		return LatencyModeUltraLow
+	}
+	return LatencyModeLow
 }
 
 type RenditionManifest struct {
@@ -206,6 +700,8 @@ type Recording struct {
 	ChannelID       string               `json:"channelId"`
 	SessionID       string               `json:"sessionId"`
 	Title           string               `json:"title"`
+	Description     string               `json:"description,omitempty"`
+	Tags            []string             `json:"tags,omitempty"`
 	DurationSeconds int                  `json:"durationSeconds"`
 	PlaybackBaseURL string               `json:"playbackBaseUrl,omitempty"`
 	Renditions      []RecordingRendition `json:"renditions,omitempty"`
@@ -215,6 +711,44 @@ type Recording struct {
 	CreatedAt       time.Time            `json:"createdAt"`
 	RetainUntil     *time.Time           `json:"retainUntil,omitempty"`
 	Clips           []ClipExportSummary  `json:"clips,omitempty"`
+	// SizeBytes is the storage the recording takes up: the transcoder's
+	// output plus any artifacts written to object storage.
+	SizeBytes int64 `json:"sizeBytes,omitempty"`
+	// OverQuota marks a recording created while its channel was over its
+	// storage quota. It stays unpublished until usage is back within the
+	// quota.
+	OverQuota bool `json:"overQuota,omitempty"`
+	// Chapters divide the recording for the player's chapter bar, ordered
+	// by offset. They start out as the session's stream markers.
+	Chapters []RecordingChapter `json:"chapters,omitempty"`
+	// PremiereAt schedules the recording to premiere. It stays unpublished
+	// until then, plays in sync for every viewer for its duration, and is an
+	// ordinary VOD afterwards.
+	PremiereAt *time.Time `json:"premiereAt,omitempty"`
+}
+
+// PremiereWindow returns when the recording's premiere starts and ends. ok
+// is false when no premiere was scheduled.
+func (r Recording) PremiereWindow() (start, end time.Time, ok bool) {
+	if r.PremiereAt == nil {
+		return time.Time{}, time.Time{}, false
+	}
+	start = *r.PremiereAt
+	return start, start.Add(time.Duration(r.DurationSeconds) * time.Second), true
+}
+
+// PremieringAt reports whether the recording's premiere is playing at now.
+// It only plays once the recording has been published for it.
+func (r Recording) PremieringAt(now time.Time) bool {
+	start, end, ok := r.PremiereWindow()
+	return ok && r.PublishedAt != nil && !now.Before(start) && now.Before(end)
+}
+
+// RecordingChapter starts a named section of a recording OffsetSeconds into
+// it.
+type RecordingChapter struct {
+	OffsetSeconds int    `json:"offsetSeconds"`
+	Title         string `json:"title"`
 }
 
 type RecordingRendition struct {
@@ -238,6 +772,7 @@ type Upload struct {
 	Title       string            `json:"title"`
 	Filename    string            `json:"filename"`
 	SizeBytes   int64             `json:"sizeBytes"`
+	ContentHash string            `json:"contentHash,omitempty"`
 	Status      string            `json:"status"`
 	Progress    int               `json:"progress"`
 	RecordingID *string           `json:"recordingId,omitempty"`
@@ -247,11 +782,164 @@ type Upload struct {
 	CreatedAt   time.Time         `json:"createdAt"`
 	UpdatedAt   time.Time         `json:"updatedAt"`
 	CompletedAt *time.Time        `json:"completedAt,omitempty"`
+	// OutputBytes is the size of the transcoded output, once the transcoder
+	// has reported it.
+	OutputBytes int64 `json:"outputBytes,omitempty"`
+	// Steps tracks the processing pipeline, in the order UploadSteps lists
+	// them. Progress is derived from the steps that are done.
+	Steps []UploadStep `json:"steps,omitempty"`
+}
+
+// Upload pipeline steps, in the order the upload processor runs them.
+const (
+	UploadStepProbe     = "probe"
+	UploadStepTranscode = "transcode"
+	UploadStepThumbnail = "thumbnail"
+	UploadStepPublish   = "publish"
+	UploadStepFinalize  = "finalize"
+)
+
+// States of an UploadStep.
+const (
+	UploadStepPending = "pending"
+	UploadStepRunning = "running"
+	UploadStepDone    = "done"
+	UploadStepFailed  = "failed"
+)
+
+// UploadStep is one phase of upload processing. Detail is a short,
+// creator-facing note such as the probed resolution or why the step failed.
+type UploadStep struct {
+	Name       string     `json:"name"`
+	Status     string     `json:"status"`
+	StartedAt  *time.Time `json:"startedAt,omitempty"`
+	FinishedAt *time.Time `json:"finishedAt,omitempty"`
+	Detail     string     `json:"detail,omitempty"`
 }
 
+// uploadStepWeights is how much of an upload's progress each step accounts
+// for. Transcoding dominates the wall-clock time of processing.
+var uploadStepWeights = []struct {
+	name   string
+	weight int
+}{
+	{UploadStepProbe, 10},
+	{UploadStepTranscode, 60},
+	{UploadStepThumbnail, 10},
+	{UploadStepPublish, 10},
+	{UploadStepFinalize, 10},
+}
+
+// UploadSteps returns a fresh pipeline with every step pending.
+func UploadSteps() []UploadStep {
+	steps := make([]UploadStep, 0, len(uploadStepWeights))
+	for _, entry := range uploadStepWeights {
+		steps = append(steps, UploadStep{Name: entry.name, Status: UploadStepPending})
+	}
+	return steps
+}
+
+// UploadProgress derives a completion percentage from steps by adding up
+// the weights of the steps that are done. Unknown step names carry no
+// weight.
+func UploadProgress(steps []UploadStep) int {
+	total, done := 0, 0
+	for _, entry := range uploadStepWeights {
+		total += entry.weight
+		for _, step := range steps {
+			if step.Name == entry.name && step.Status == UploadStepDone {
+				done += entry.weight
+				break
+			}
+		}
+	}
+	if total == 0 {
+		return 0
+	}
+	return done * 100 / total
+}
+
+// ValidUploadStep reports whether step names a known pipeline step in a
+// known state.
+func ValidUploadStep(step UploadStep) bool {
+	known := false
+	for _, entry := range uploadStepWeights {
+		if entry.name == step.Name {
+			known = true
+			break
+		}
+	}
+	if !known {
+		return false
+	}
+	switch step.Status {
+	case UploadStepPending, UploadStepRunning, UploadStepDone, UploadStepFailed:
+		return true
+	}
+	return false
+}
+
+// ApplyStep records step on the upload, replacing the step of the same name
+// and recomputing Progress. A step without StartedAt keeps the start time
+// already recorded, so finishing a step need not repeat it.
+func (u *Upload) ApplyStep(step UploadStep) {
+	if len(u.Steps) == 0 {
+		u.Steps = UploadSteps()
+	}
+	for i := range u.Steps {
+		if u.Steps[i].Name != step.Name {
+			continue
+		}
+		if step.StartedAt == nil {
+			step.StartedAt = u.Steps[i].StartedAt
+		}
+		u.Steps[i] = step
+		u.Progress = UploadProgress(u.Steps)
+		return
+	}
+	u.Steps = append(u.Steps, step)
+	u.Progress = UploadProgress(u.Steps)
+}
+
+// FailedStep returns the step that failed, if any, so clients can point at
+// where processing stopped.
+func (u Upload) FailedStep() (UploadStep, bool) {
+	for _, step := range u.Steps {
+		if step.Status == UploadStepFailed {
+			return step, true
+		}
+	}
+	return UploadStep{}, false
+}
+
+// StorageBytes is what the upload counts against its channel's storage
+// quota: the transcoded output once its size is known, and the source size
+// until then.
+func (u Upload) StorageBytes() int64 {
+	if u.OutputBytes > 0 {
+		return u.OutputBytes
+	}
+	return u.SizeBytes
+}
+
+// ChannelStorage tracks the VOD storage a channel uses. UsedBytes is the
+// running total of its recordings and uploads, kept in step as they are
+// created and deleted. QuotaBytes is an admin override of the platform
+// quota; nil uses the platform default and zero means unlimited.
+type ChannelStorage struct {
+	ChannelID  string    `json:"channelId"`
+	UsedBytes  int64     `json:"usedBytes"`
+	QuotaBytes *int64    `json:"quotaBytes,omitempty"`
+	UpdatedAt  time.Time `json:"updatedAt"`
+}
+
+// ClipExport is a clip of a recording. Clips cut while the stream is live
+// carry only the session until StopStream links them to its recording, so
+// RecordingID is empty in the meantime. CreatedBy names the viewer who cut a
+// live clip.
 type ClipExport struct {
 	ID            string     `json:"id"`
-	RecordingID   string     `json:"recordingId"`
+	RecordingID   string     `json:"recordingId,omitempty"`
 	ChannelID     string     `json:"channelId"`
 	SessionID     string     `json:"sessionId"`
 	Title         string     `json:"title"`
@@ -262,6 +950,100 @@ type ClipExport struct {
 	CreatedAt     time.Time  `json:"createdAt"`
 	CompletedAt   *time.Time `json:"completedAt,omitempty"`
 	StorageObject string     `json:"storageObject,omitempty"`
+	CreatedBy     string     `json:"createdBy,omitempty"`
+}
+
+// StreamMarker flags a moment in a live session. OffsetSeconds counts from
+// the session's start, which is also where its recording starts.
+type StreamMarker struct {
+	ID            string    `json:"id"`
+	ChannelID     string    `json:"channelId"`
+	SessionID     string    `json:"sessionId"`
+	Label         string    `json:"label"`
+	OffsetSeconds int       `json:"offsetSeconds"`
+	CreatedBy     string    `json:"createdBy,omitempty"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// RestreamTarget is an external RTMP destination a channel simulcasts to
+// while it is live. StreamKeyCiphertext is the destination's stream key
+// sealed with the server secret key; the plaintext is never stored and the
+// API never returns either form.
+type RestreamTarget struct {
+	ID                  string    `json:"id"`
+	ChannelID           string    `json:"channelId"`
+	Label               string    `json:"label"`
+	URL                 string    `json:"url"`
+	StreamKeyCiphertext string    `json:"streamKeyCiphertext"`
+	Enabled             bool      `json:"enabled"`
+	CreatedAt           time.Time `json:"createdAt"`
+	UpdatedAt           time.Time `json:"updatedAt"`
+}
+
+// Co-stream invite statuses. Pending and accepted invites are active; the
+// rest are final.
+const (
+	CoStreamInvitePending  = "pending"
+	CoStreamInviteAccepted = "accepted"
+	CoStreamInviteDeclined = "declined"
+	// CoStreamInviteRemoved marks an invite the host withdrew or a guest
+	// left.
+	CoStreamInviteRemoved = "removed"
+	// CoStreamInviteEnded marks an invite whose session has ended.
+	CoStreamInviteEnded = "ended"
+)
+
+// CoStreamInvite asks a guest channel to co-stream a host's live session.
+// Once the guest's owner accepts, the guest channel's page mirrors the
+// host's session until the stream ends or the guest is removed.
+type CoStreamInvite struct {
+	ID             string     `json:"id"`
+	SessionID      string     `json:"sessionId"`
+	HostChannelID  string     `json:"hostChannelId"`
+	GuestChannelID string     `json:"guestChannelId"`
+	InvitedBy      string     `json:"invitedBy,omitempty"`
+	Status         string     `json:"status"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	RespondedAt    *time.Time `json:"respondedAt,omitempty"`
+	EndedAt        *time.Time `json:"endedAt,omitempty"`
+}
+
+// Active reports whether the invite is still pending or accepted.
+func (i CoStreamInvite) Active() bool {
+	return i.Status == CoStreamInvitePending || i.Status == CoStreamInviteAccepted
+}
+
+// ScheduleRecurrenceWeekly repeats a schedule entry every week at the same
+// local time in its time zone.
+const ScheduleRecurrenceWeekly = "weekly"
+
+// ScheduleEntry is a stream a channel announces ahead of time. StartsAt is
+// the first occurrence. A weekly entry repeats at that wall-clock time in
+// TimeZone, so it follows daylight saving changes, until RepeatUntil.
+// Sequence counts edits so calendar clients replace their copy instead of
+// keeping a stale one.
+type ScheduleEntry struct {
+	ID              string     `json:"id"`
+	ChannelID       string     `json:"channelId"`
+	Title           string     `json:"title"`
+	Category        string     `json:"category,omitempty"`
+	StartsAt        time.Time  `json:"startsAt"`
+	DurationMinutes int        `json:"durationMinutes"`
+	TimeZone        string     `json:"timeZone"`
+	Recurrence      string     `json:"recurrence,omitempty"`
+	RepeatUntil     *time.Time `json:"repeatUntil,omitempty"`
+	Sequence        int        `json:"sequence"`
+	CreatedAt       time.Time  `json:"createdAt"`
+	UpdatedAt       time.Time  `json:"updatedAt"`
+}
+
+// ScheduleFeedToken lets a calendar app fetch the schedule of the channels
+// a user follows. The feed URL carries Nonce signed with the server secret
+// key; issuing a new token or deleting it invalidates the old URL.
+type ScheduleFeedToken struct {
+	UserID    string    `json:"userId"`
+	Nonce     string    `json:"nonce"`
+	CreatedAt time.Time `json:"createdAt"`
 }
 
 type ClipExportSummary struct {
@@ -272,29 +1054,83 @@ type ClipExportSummary struct {
 	Status       string `json:"status"`
 }
 
+// ChatMessage is a message posted to a channel's chat. ClientMessageID is the
+// optional ID the sender generated to recognise the message and to have
+// retried submissions deduplicated. Seq is the channel's chat sequence number
+// the gateway assigned to the message, or zero for messages sent before
+// sequencing.
 type ChatMessage struct {
-	ID        string    `json:"id"`
+	ID              string    `json:"id"`
+	ChannelID       string    `json:"channelId"`
+	UserID          string    `json:"userId"`
+	Content         string    `json:"content"`
+	ClientMessageID string    `json:"clientMessageId,omitempty"`
+	Seq             int64     `json:"seq,omitempty"`
+	CreatedAt       time.Time `json:"createdAt"`
+}
+
+// ChatPin is the message pinned above a channel's chat. A channel has at most
+// one. Pinning an existing message copies its content and author into
+// MessageID and AuthorID; a free-text pin has neither.
+type ChatPin struct {
 	ChannelID string    `json:"channelId"`
-	UserID    string    `json:"userId"`
+	MessageID string    `json:"messageId,omitempty"`
+	AuthorID  string    `json:"authorId,omitempty"`
 	Content   string    `json:"content"`
-	CreatedAt time.Time `json:"createdAt"`
+	PinnedBy  string    `json:"pinnedBy"`
+	PinnedAt  time.Time `json:"pinnedAt"`
 }
 
+// ChatReport is a viewer's report of a chat message, a live session, a
+// recording, or a whole channel. SubjectType says which, and SubjectID names
+// it. TargetID is the author of a reported message and otherwise the owner
+// of the channel.
 type ChatReport struct {
-	ID          string     `json:"id"`
-	ChannelID   string     `json:"channelId"`
-	ReporterID  string     `json:"reporterId"`
-	TargetID    string     `json:"targetId"`
-	Reason      string     `json:"reason"`
-	MessageID   string     `json:"messageId,omitempty"`
-	EvidenceURL string     `json:"evidenceUrl,omitempty"`
-	Status      string     `json:"status"`
-	Resolution  string     `json:"resolution,omitempty"`
-	ResolverID  string     `json:"resolverId,omitempty"`
-	CreatedAt   time.Time  `json:"createdAt"`
-	ResolvedAt  *time.Time `json:"resolvedAt,omitempty"`
+	ID          string          `json:"id"`
+	ChannelID   string          `json:"channelId"`
+	ReporterID  string          `json:"reporterId"`
+	TargetID    string          `json:"targetId"`
+	SubjectType string          `json:"subjectType"`
+	SubjectID   string          `json:"subjectId,omitempty"`
+	Category    string          `json:"category"`
+	Reason      string          `json:"reason"`
+	MessageID   string          `json:"messageId,omitempty"`
+	EvidenceURL string          `json:"evidenceUrl,omitempty"`
+	Claimant    *ReportClaimant `json:"claimant,omitempty"`
+	Status      string          `json:"status"`
+	Resolution  string          `json:"resolution,omitempty"`
+	ResolverID  string          `json:"resolverId,omitempty"`
+	CreatedAt   time.Time       `json:"createdAt"`
+	ResolvedAt  *time.Time      `json:"resolvedAt,omitempty"`
+}
+
+// ReportClaimant is the rights holder behind a copyright report, kept so
+// moderators can follow up on the claim.
+type ReportClaimant struct {
+	Name    string `json:"name"`
+	Email   string `json:"email"`
+	Phone   string `json:"phone,omitempty"`
+	Address string `json:"address,omitempty"`
 }
 
+// ChatReport.SubjectType values.
+const (
+	ReportSubjectChatMessage   = "chat_message"
+	ReportSubjectStreamSession = "stream_session"
+	ReportSubjectRecording     = "recording"
+	ReportSubjectChannel       = "channel"
+)
+
+// ChatReport.Category values. Chat reports filed before categories existed
+// are "other".
+const (
+	ReportCategoryIllegal    = "illegal"
+	ReportCategoryExplicit   = "explicit"
+	ReportCategoryHarassment = "harassment"
+	ReportCategoryCopyright  = "copyright"
+	ReportCategoryOther      = "other"
+)
+
 type ChatRestriction struct {
 	ID        string     `json:"id"`
 	Type      string     `json:"type"`
@@ -306,6 +1142,86 @@ type ChatRestriction struct {
 	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
 }
 
+// ChatAppeal is a banned or timed-out viewer's request to have the
+// restriction lifted. Restriction records which one was in force when the
+// appeal was filed.
+type ChatAppeal struct {
+	ID             string     `json:"id"`
+	ChannelID      string     `json:"channelId"`
+	UserID         string     `json:"userId"`
+	Restriction    string     `json:"restriction"`
+	Message        string     `json:"message"`
+	Status         string     `json:"status"`
+	ResolverID     string     `json:"resolverId,omitempty"`
+	ResolutionNote string     `json:"resolutionNote,omitempty"`
+	CreatedAt      time.Time  `json:"createdAt"`
+	ResolvedAt     *time.Time `json:"resolvedAt,omitempty"`
+}
+
+// ModerationAction is one entry in a channel's append-only moderation log
+// about TargetID. ActorID is empty once the moderator's account is deleted.
+// Metadata carries details specific to Action, such as a timeout's expiry or
+// a deleted message's ID and content. Seq is the chat sequence number of the
+// moderation event that produced the entry, or zero for entries made outside
+// the chat stream.
+type ModerationAction struct {
+	ID        string            `json:"id"`
+	ChannelID string            `json:"channelId"`
+	TargetID  string            `json:"targetId"`
+	ActorID   string            `json:"actorId,omitempty"`
+	Action    string            `json:"action"`
+	Reason    string            `json:"reason,omitempty"`
+	Metadata  map[string]string `json:"metadata,omitempty"`
+	Seq       int64             `json:"seq,omitempty"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+// ModerationAction.Action values. The first five match the chat moderation
+// actions of the same name.
+const (
+	ModerationActionBan           = "ban"
+	ModerationActionUnban         = "unban"
+	ModerationActionTimeout       = "timeout"
+	ModerationActionRemoveTimeout = "remove_timeout"
+	ModerationActionDeleteMessage = "delete_message"
+	ModerationActionResolveReport = "resolve_report"
+)
+
+// BadgeDefinition describes a global badge, such as staff or verified, that
+// administrators grant to users. Definitions are data rather than code so a
+// new badge does not need a deploy.
+type BadgeDefinition struct {
+	Slug      string    `json:"slug"`
+	Label     string    `json:"label"`
+	IconURL   string    `json:"iconUrl,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// BadgeGrant records that a user holds a global badge, which chat shows next
+// to their name on every channel.
+type BadgeGrant struct {
+	UserID    string    `json:"userId"`
+	Slug      string    `json:"slug"`
+	GrantedBy string    `json:"grantedBy,omitempty"`
+	GrantedAt time.Time `json:"grantedAt"`
+}
+
+// FeatureFlag gates a feature that is being rolled out. A disabled flag is
+// off for everyone. Otherwise users on DenyUserIDs never get it, users on
+// AllowUserIDs always do, and everyone else gets it when their bucket falls
+// below RolloutPercent.
+type FeatureFlag struct {
+	Key            string    `json:"key"`
+	Description    string    `json:"description,omitempty"`
+	Enabled        bool      `json:"enabled"`
+	RolloutPercent int       `json:"rolloutPercent"`
+	AllowUserIDs   []string  `json:"allowUserIds,omitempty"`
+	DenyUserIDs    []string  `json:"denyUserIds,omitempty"`
+	CreatedAt      time.Time `json:"createdAt"`
+	UpdatedAt      time.Time `json:"updatedAt"`
+}
+
 // Tip describes a viewer tip recorded for a channel. Amount uses the fixed
 // precision Money type (1e-8 minor units) while the public JSON API continues to
 // expose human-readable decimal values.
@@ -342,12 +1258,26 @@ type Subscription struct {
 	CancelledReason   string     `json:"cancelledReason,omitempty"`
 	CancelledAt       *time.Time `json:"cancelledAt,omitempty"`
 	ExternalReference string     `json:"externalReference,omitempty"`
+	GifterID          string     `json:"gifterId,omitempty"`
+	IsGift            bool       `json:"isGift,omitempty"`
 }
 
+// CryptoCurrency names a currency a donation address can be listed for.
+type CryptoCurrency string
+
+const (
+	CryptoCurrencyBTC CryptoCurrency = "BTC"
+	CryptoCurrencyETH CryptoCurrency = "ETH"
+	CryptoCurrencyXMR CryptoCurrency = "XMR"
+)
+
 type CryptoAddress struct {
-	Currency string `json:"currency"`
-	Address  string `json:"address"`
-	Note     string `json:"note,omitempty"`
+	Currency CryptoCurrency `json:"currency"`
+	Address  string         `json:"address"`
+	Note     string         `json:"note,omitempty"`
+	// VerifiedAt is when the owner proved control of the address by signing
+	// a challenge with its wallet. Nil means unverified.
+	VerifiedAt *time.Time `json:"verifiedAt,omitempty"`
 }
 
 type SocialLink struct {
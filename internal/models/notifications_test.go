@@ -0,0 +1,27 @@
+package models
+
+import "testing"
+
+func TestNotificationPreferencesAllows(t *testing.T) {
+	prefs := DefaultNotificationPreferences("user")
+	prefs.Mentions.InApp = false
+
+	cases := []struct {
+		category string
+		delivery string
+		want     bool
+	}{
+		{NotificationCategoryFollowedLive, NotificationDeliveryInApp, true},
+		{NotificationCategoryFollowedLive, NotificationDeliveryEmail, false},
+		{NotificationCategoryMentions, NotificationDeliveryInApp, false},
+		{NotificationCategoryModeration, NotificationDeliveryEmail, true},
+		{NotificationCategoryAccount, NotificationDeliveryEmail, true},
+		{"marketing", NotificationDeliveryInApp, false},
+		{NotificationCategoryAccount, "sms", false},
+	}
+	for _, tc := range cases {
+		if got := prefs.Allows(tc.category, tc.delivery); got != tc.want {
+			t.Fatalf("Allows(%q, %q) = %v, want %v", tc.category, tc.delivery, got, tc.want)
+		}
+	}
+}
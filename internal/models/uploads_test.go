@@ -0,0 +1,68 @@
+package models
+
+import (
+	"testing"
+	"time"
+)
+
+func TestUploadProgressFollowsStepWeights(t *testing.T) {
+	steps := UploadSteps()
+	if got := UploadProgress(steps); got != 0 {
+		t.Fatalf("expected a fresh pipeline at 0%%, got %d", got)
+	}
+	cases := []struct {
+		done []string
+		want int
+	}{
+		{done: []string{UploadStepProbe}, want: 10},
+		{done: []string{UploadStepProbe, UploadStepTranscode}, want: 70},
+		{done: []string{UploadStepProbe, UploadStepThumbnail, UploadStepPublish, UploadStepFinalize}, want: 40},
+		{done: []string{UploadStepProbe, UploadStepTranscode, UploadStepThumbnail, UploadStepPublish, UploadStepFinalize}, want: 100},
+	}
+	for _, tc := range cases {
+		steps := UploadSteps()
+		for i := range steps {
+			for _, name := range tc.done {
+				if steps[i].Name == name {
+					steps[i].Status = UploadStepDone
+				}
+			}
+		}
+		if got := UploadProgress(steps); got != tc.want {
+			t.Fatalf("expected %d%% with %v done, got %d", tc.want, tc.done, got)
+		}
+	}
+
+	running := []UploadStep{{Name: UploadStepTranscode, Status: UploadStepRunning}, {Name: "extra", Status: UploadStepDone}}
+	if got := UploadProgress(running); got != 0 {
+		t.Fatalf("expected running and unknown steps to carry no weight, got %d", got)
+	}
+}
+
+func TestUploadApplyStep(t *testing.T) {
+	var upload Upload
+	started := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	upload.ApplyStep(UploadStep{Name: UploadStepProbe, Status: UploadStepRunning, StartedAt: &started})
+	if len(upload.Steps) != 5 || upload.Steps[0].Status != UploadStepRunning || upload.Progress != 0 {
+		t.Fatalf("expected the pipeline created with the probe running, got %+v", upload)
+	}
+
+	finished := started.Add(time.Second)
+	upload.ApplyStep(UploadStep{Name: UploadStepProbe, Status: UploadStepDone, FinishedAt: &finished})
+	probe := upload.Steps[0]
+	if probe.StartedAt == nil || !probe.StartedAt.Equal(started) || probe.FinishedAt == nil || upload.Progress != 10 {
+		t.Fatalf("expected the probe to keep its start time and count, got %+v at %d%%", probe, upload.Progress)
+	}
+	if _, ok := upload.FailedStep(); ok {
+		t.Fatal("expected no failed step")
+	}
+
+	upload.ApplyStep(UploadStep{Name: UploadStepTranscode, Status: UploadStepFailed, FinishedAt: &finished, Detail: "out of disk"})
+	failed, ok := upload.FailedStep()
+	if !ok || failed.Name != UploadStepTranscode || failed.Detail != "out of disk" {
+		t.Fatalf("expected the transcode to be the failed step, got %+v (ok %v)", failed, ok)
+	}
+	if upload.Progress != 10 {
+		t.Fatalf("expected a failed step not to count, got %d%%", upload.Progress)
+	}
+}
@@ -0,0 +1,3 @@
+// Package health tracks the liveness of background components such as queue
+// workers and purge loops so operators can tell when one has stopped.
+package health
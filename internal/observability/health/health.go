@@ -0,0 +1,216 @@
+package health
+
+import (
+	"sort"
+	"sync"
+	"time"
+)
+
+// DefaultStaleAfter is how long a component may go without a heartbeat before
+// it is reported as stalled.
+const DefaultStaleAfter = 2 * time.Minute
+
+// Component statuses reported by Snapshot.
+const (
+	StatusOK       = "ok"
+	StatusStarting = "starting"
+	StatusDegraded = "degraded"
+	StatusStalled  = "stalled"
+)
+
+// Config tunes a Registry.
+type Config struct {
+	// StaleAfter is the default heartbeat age at which components are
+	// reported as stalled. Zero uses DefaultStaleAfter.
+	StaleAfter time.Duration
+	// Now returns the current time. Tests inject a fake clock; nil falls
+	// back to time.Now.
+	Now func() time.Time
+}
+
+// Registry holds the background components registered by the process.
+type Registry struct {
+	staleAfter time.Duration
+	now        func() time.Time
+
+	mu         sync.RWMutex
+	components map[string]*Component
+}
+
+// NewRegistry constructs an empty registry.
+func NewRegistry(cfg Config) *Registry {
+	staleAfter := cfg.StaleAfter
+	if staleAfter <= 0 {
+		staleAfter = DefaultStaleAfter
+	}
+	now := cfg.Now
+	if now == nil {
+		now = time.Now
+	}
+	return &Registry{staleAfter: staleAfter, now: now, components: make(map[string]*Component)}
+}
+
+// ComponentOption customises a registered component.
+type ComponentOption func(*Component)
+
+// WithStaleAfter overrides the registry's stall threshold for one component,
+// for loops that legitimately tick less often. A negative value disables
+// stall detection for request-driven components that only report errors.
+func WithStaleAfter(d time.Duration) ComponentOption {
+	return func(c *Component) {
+		c.staleAfter = d
+	}
+}
+
+// RegisterComponent returns the handle a background component reports
+// through. Registering a name again returns the existing handle. A nil
+// registry returns a nil handle, whose methods are no-ops.
+func (r *Registry) RegisterComponent(name string, opts ...ComponentOption) *Component {
+	if r == nil {
+		return nil
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if existing, ok := r.components[name]; ok {
+		return existing
+	}
+	component := &Component{
+		name:         name,
+		now:          r.now,
+		staleAfter:   r.staleAfter,
+		registeredAt: r.now(),
+	}
+	for _, opt := range opts {
+		opt(component)
+	}
+	r.components[name] = component
+	return component
+}
+
+// Snapshot reports every registered component, sorted by name.
+func (r *Registry) Snapshot() []ComponentSnapshot {
+	if r == nil {
+		return nil
+	}
+	r.mu.RLock()
+	components := make([]*Component, 0, len(r.components))
+	for _, component := range r.components {
+		components = append(components, component)
+	}
+	r.mu.RUnlock()
+
+	now := r.now()
+	snapshots := make([]ComponentSnapshot, 0, len(components))
+	for _, component := range components {
+		snapshots = append(snapshots, component.snapshot(now))
+	}
+	sort.Slice(snapshots, func(i, j int) bool { return snapshots[i].Name < snapshots[j].Name })
+	return snapshots
+}
+
+// Component is the handle a background loop reports its health through. All
+// methods are safe for concurrent use and do nothing on a nil Component.
+type Component struct {
+	name         string
+	now          func() time.Time
+	staleAfter   time.Duration
+	registeredAt time.Time
+
+	mu            sync.Mutex
+	degraded      bool
+	lastHeartbeat time.Time
+	lastError     string
+	lastErrorAt   time.Time
+}
+
+// Heartbeat records that the component's loop is still running without
+// changing its healthy or degraded state.
+func (c *Component) Heartbeat() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.lastHeartbeat = c.now()
+	c.mu.Unlock()
+}
+
+// SetHealthy records a heartbeat and clears any degraded state.
+func (c *Component) SetHealthy() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	c.lastHeartbeat = c.now()
+	c.degraded = false
+	c.mu.Unlock()
+}
+
+// SetDegraded records a heartbeat and marks the component degraded until the
+// next SetHealthy. The error is kept as the component's last error.
+func (c *Component) SetDegraded(err error) {
+	if c == nil {
+		return
+	}
+	now := c.now()
+	c.mu.Lock()
+	c.lastHeartbeat = now
+	c.degraded = true
+	if err != nil {
+		c.lastError = err.Error()
+		c.lastErrorAt = now
+	}
+	c.mu.Unlock()
+}
+
+// HeartbeatInterval suggests how often an idle loop should call Heartbeat so
+// it is never reported as stalled.
+func (c *Component) HeartbeatInterval() time.Duration {
+	if c == nil || c.staleAfter <= 0 {
+		return DefaultStaleAfter / 4
+	}
+	return c.staleAfter / 4
+}
+
+func (c *Component) snapshot(now time.Time) ComponentSnapshot {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	snapshot := ComponentSnapshot{
+		Name:        c.name,
+		Status:      StatusOK,
+		LastError:   c.lastError,
+		LastErrorAt: c.lastErrorAt,
+		StaleAfter:  c.staleAfter,
+	}
+	if !c.lastHeartbeat.IsZero() {
+		snapshot.LastHeartbeat = c.lastHeartbeat
+	}
+	since := c.lastHeartbeat
+	if since.IsZero() {
+		since = c.registeredAt
+	}
+	switch {
+	case c.staleAfter > 0 && now.Sub(since) > c.staleAfter:
+		snapshot.Status = StatusStalled
+	case c.degraded:
+		snapshot.Status = StatusDegraded
+	case c.lastHeartbeat.IsZero() && c.staleAfter > 0:
+		snapshot.Status = StatusStarting
+	}
+	return snapshot
+}
+
+// ComponentSnapshot is a point-in-time view of a component. LastHeartbeat and
+// LastErrorAt are zero when the component has never reported.
+type ComponentSnapshot struct {
+	Name          string
+	Status        string
+	LastHeartbeat time.Time
+	LastError     string
+	LastErrorAt   time.Time
+	StaleAfter    time.Duration
+}
+
+// Healthy reports whether the component is neither degraded nor stalled.
+func (s ComponentSnapshot) Healthy() bool {
+	return s.Status == StatusOK || s.Status == StatusStarting
+}
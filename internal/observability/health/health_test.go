@@ -0,0 +1,119 @@
+package health
+
+import (
+	"errors"
+	"sync"
+	"testing"
+	"time"
+)
+
+type fakeClock struct {
+	mu  sync.Mutex
+	now time.Time
+}
+
+func (c *fakeClock) Now() time.Time {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.now
+}
+
+func (c *fakeClock) Advance(d time.Duration) {
+	c.mu.Lock()
+	c.now = c.now.Add(d)
+	c.mu.Unlock()
+}
+
+func snapshotFor(t *testing.T, registry *Registry, name string) ComponentSnapshot {
+	t.Helper()
+	for _, snapshot := range registry.Snapshot() {
+		if snapshot.Name == name {
+			return snapshot
+		}
+	}
+	t.Fatalf("component %s not registered", name)
+	return ComponentSnapshot{}
+}
+
+func TestComponentStalledAfterMissedHeartbeats(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	registry := NewRegistry(Config{StaleAfter: time.Minute, Now: clock.Now})
+	worker := registry.RegisterComponent("worker")
+
+	if got := snapshotFor(t, registry, "worker").Status; got != StatusStarting {
+		t.Fatalf("expected starting before the first heartbeat, got %s", got)
+	}
+	clock.Advance(2 * time.Minute)
+	if got := snapshotFor(t, registry, "worker").Status; got != StatusStalled {
+		t.Fatalf("expected stalled when the first heartbeat never arrives, got %s", got)
+	}
+
+	worker.Heartbeat()
+	snapshot := snapshotFor(t, registry, "worker")
+	if snapshot.Status != StatusOK || !snapshot.LastHeartbeat.Equal(clock.Now()) {
+		t.Fatalf("expected ok with a fresh heartbeat, got %+v", snapshot)
+	}
+
+	clock.Advance(time.Minute)
+	if got := snapshotFor(t, registry, "worker").Status; got != StatusOK {
+		t.Fatalf("expected ok at exactly the threshold, got %s", got)
+	}
+	clock.Advance(time.Second)
+	if got := snapshotFor(t, registry, "worker").Status; got != StatusStalled {
+		t.Fatalf("expected stalled past the threshold, got %s", got)
+	}
+
+	passive := registry.RegisterComponent("gateway", WithStaleAfter(-1))
+	clock.Advance(time.Hour)
+	if got := snapshotFor(t, registry, "gateway").Status; got != StatusOK {
+		t.Fatalf("expected components without stall detection to stay ok, got %s", got)
+	}
+	if registry.RegisterComponent("gateway") != passive {
+		t.Fatal("expected registering a name twice to return the same handle")
+	}
+}
+
+func TestComponentDegradedErrorPropagation(t *testing.T) {
+	clock := &fakeClock{now: time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)}
+	registry := NewRegistry(Config{StaleAfter: time.Minute, Now: clock.Now})
+	worker := registry.RegisterComponent("worker")
+
+	worker.SetDegraded(errors.New("queue unavailable"))
+	failedAt := clock.Now()
+	snapshot := snapshotFor(t, registry, "worker")
+	if snapshot.Status != StatusDegraded || snapshot.LastError != "queue unavailable" || !snapshot.LastErrorAt.Equal(failedAt) {
+		t.Fatalf("expected degraded with the error recorded, got %+v", snapshot)
+	}
+	if snapshot.Healthy() {
+		t.Fatal("expected degraded component to be unhealthy")
+	}
+
+	clock.Advance(10 * time.Second)
+	worker.Heartbeat()
+	if got := snapshotFor(t, registry, "worker").Status; got != StatusDegraded {
+		t.Fatalf("expected heartbeat to keep the degraded state, got %s", got)
+	}
+
+	worker.SetHealthy()
+	snapshot = snapshotFor(t, registry, "worker")
+	if snapshot.Status != StatusOK || !snapshot.Healthy() {
+		t.Fatalf("expected SetHealthy to clear the degraded state, got %+v", snapshot)
+	}
+	if snapshot.LastError != "queue unavailable" || !snapshot.LastErrorAt.Equal(failedAt) {
+		t.Fatalf("expected the last error to be kept after recovery, got %+v", snapshot)
+	}
+}
+
+func TestNilComponentIsNoop(t *testing.T) {
+	var registry *Registry
+	component := registry.RegisterComponent("worker")
+	component.Heartbeat()
+	component.SetHealthy()
+	component.SetDegraded(errors.New("ignored"))
+	if component.HeartbeatInterval() <= 0 {
+		t.Fatal("expected a positive heartbeat interval")
+	}
+	if registry.Snapshot() != nil {
+		t.Fatal("expected nil registry to report no components")
+	}
+}
@@ -0,0 +1,230 @@
+package logging
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sort"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// componentKey is the attribute WithComponent attaches, and the one the
+// leveled handler watches for to pick up component overrides.
+const componentKey = "component"
+
+// ParseLevel parses a level name strictly, unlike Config.Level which falls
+// back to info for anything it does not recognise.
+func ParseLevel(level string) (slog.Level, error) {
+	switch strings.ToLower(strings.TrimSpace(level)) {
+	case "debug":
+		return slog.LevelDebug, nil
+	case "info":
+		return slog.LevelInfo, nil
+	case "warn", "warning":
+		return slog.LevelWarn, nil
+	case "error":
+		return slog.LevelError, nil
+	}
+	return slog.LevelInfo, fmt.Errorf("unknown log level %q", level)
+}
+
+// LevelName formats level the way ParseLevel reads it.
+func LevelName(level slog.Level) string {
+	return strings.ToLower(level.String())
+}
+
+// componentLevel is the override slot of one component. Loggers resolve
+// their slot once when they are built, so checking a record's level is a
+// couple of atomic loads rather than a map lookup.
+type componentLevel struct {
+	level atomic.Int64
+	set   atomic.Bool
+}
+
+type levelTimer interface {
+	Stop() bool
+}
+
+// LevelState is a snapshot of a LevelController.
+type LevelState struct {
+	Level slog.Level
+	// Components holds the per-component overrides in effect.
+	Components map[string]slog.Level
+	// RevertAt is when the levels go back to their startup values, or zero
+	// when no revert is pending.
+	RevertAt time.Time
+}
+
+// LevelController holds the levels of the loggers built with it: a global
+// level plus overrides for individual components, all adjustable while the
+// process runs.
+type LevelController struct {
+	level   atomic.Int64
+	startup slog.Level
+
+	mu         sync.Mutex
+	components map[string]*componentLevel
+	overridden []string
+	revertGen  uint64
+	revert     levelTimer
+	revertAt   time.Time
+
+	now       func() time.Time
+	afterFunc func(time.Duration, func()) levelTimer
+}
+
+// NewLevelController returns a controller starting at level, read as
+// Config.Level is.
+func NewLevelController(level string) *LevelController {
+	c := &LevelController{
+		startup:    parseLevel(level).Level(),
+		components: make(map[string]*componentLevel),
+		now:        time.Now,
+		afterFunc: func(d time.Duration, f func()) levelTimer {
+			return time.AfterFunc(d, f)
+		},
+	}
+	c.level.Store(int64(c.startup))
+	return c
+}
+
+// StartupLevel is the global level the controller was created with.
+func (c *LevelController) StartupLevel() slog.Level {
+	return c.startup
+}
+
+// State returns the levels currently in effect.
+func (c *LevelController) State() LevelState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.stateLocked()
+}
+
+func (c *LevelController) stateLocked() LevelState {
+	state := LevelState{
+		Level:      slog.Level(c.level.Load()),
+		Components: make(map[string]slog.Level, len(c.overridden)),
+		RevertAt:   c.revertAt,
+	}
+	for _, name := range c.overridden {
+		state.Components[name] = slog.Level(c.components[name].level.Load())
+	}
+	return state
+}
+
+// Set replaces the global level and every component override. A positive
+// revertAfter resets the levels to their startup values once it elapses;
+// otherwise the new levels stay until changed again. Either way a revert
+// scheduled by an earlier Set is cancelled.
+func (c *LevelController) Set(level slog.Level, components map[string]slog.Level, revertAfter time.Duration) LevelState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.applyLocked(level, components)
+	c.cancelRevertLocked()
+	if revertAfter > 0 {
+		gen := c.revertGen
+		c.revertAt = c.now().Add(revertAfter)
+		c.revert = c.afterFunc(revertAfter, func() { c.revertIf(gen) })
+	}
+	return c.stateLocked()
+}
+
+// Reset restores the startup levels and cancels any pending revert.
+func (c *LevelController) Reset() LevelState {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.applyLocked(c.startup, nil)
+	c.cancelRevertLocked()
+	return c.stateLocked()
+}
+
+func (c *LevelController) revertIf(gen uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if gen != c.revertGen {
+		return
+	}
+	c.applyLocked(c.startup, nil)
+	c.revert = nil
+	c.revertAt = time.Time{}
+}
+
+func (c *LevelController) cancelRevertLocked() {
+	c.revertGen++
+	if c.revert != nil {
+		c.revert.Stop()
+		c.revert = nil
+	}
+	c.revertAt = time.Time{}
+}
+
+func (c *LevelController) applyLocked(level slog.Level, components map[string]slog.Level) {
+	c.level.Store(int64(level))
+	for _, name := range c.overridden {
+		c.components[name].set.Store(false)
+	}
+	c.overridden = c.overridden[:0]
+	for name, componentLevel := range components {
+		slot := c.slotLocked(name)
+		slot.level.Store(int64(componentLevel))
+		slot.set.Store(true)
+		c.overridden = append(c.overridden, name)
+	}
+	sort.Strings(c.overridden)
+}
+
+// slot returns the override slot of component, creating it on first use.
+func (c *LevelController) slot(component string) *componentLevel {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.slotLocked(component)
+}
+
+func (c *LevelController) slotLocked(component string) *componentLevel {
+	slot, ok := c.components[component]
+	if !ok {
+		slot = &componentLevel{}
+		c.components[component] = slot
+	}
+	return slot
+}
+
+func (c *LevelController) enabled(component *componentLevel, level slog.Level) bool {
+	if component != nil && component.set.Load() {
+		return level >= slog.Level(component.level.Load())
+	}
+	return level >= slog.Level(c.level.Load())
+}
+
+// leveledHandler filters records by the controller's levels before passing
+// them to the wrapped handler, which is built to accept every level.
+type leveledHandler struct {
+	inner     slog.Handler
+	levels    *LevelController
+	component *componentLevel
+}
+
+func (h *leveledHandler) Enabled(_ context.Context, level slog.Level) bool {
+	return h.levels.enabled(h.component, level)
+}
+
+func (h *leveledHandler) Handle(ctx context.Context, record slog.Record) error {
+	return h.inner.Handle(ctx, record)
+}
+
+func (h *leveledHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	component := h.component
+	for _, attr := range attrs {
+		if attr.Key == componentKey && attr.Value.Kind() == slog.KindString {
+			component = h.levels.slot(attr.Value.String())
+		}
+	}
+	return &leveledHandler{inner: h.inner.WithAttrs(attrs), levels: h.levels, component: component}
+}
+
+func (h *leveledHandler) WithGroup(name string) slog.Handler {
+	return &leveledHandler{inner: h.inner.WithGroup(name), levels: h.levels, component: h.component}
+}
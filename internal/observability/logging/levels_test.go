@@ -0,0 +1,163 @@
+package logging
+
+import (
+	"bytes"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+)
+
+type fakeLevelTimer struct {
+	stopped bool
+}
+
+func (t *fakeLevelTimer) Stop() bool {
+	t.stopped = true
+	return true
+}
+
+// fakeRevertClock stands in for time.AfterFunc, recording scheduled reverts
+// so tests can fire them.
+type fakeRevertClock struct {
+	now     time.Time
+	pending []func()
+	timers  []*fakeLevelTimer
+}
+
+func (c *fakeRevertClock) install(levels *LevelController) {
+	levels.now = func() time.Time { return c.now }
+	levels.afterFunc = func(d time.Duration, f func()) levelTimer {
+		timer := &fakeLevelTimer{}
+		c.pending = append(c.pending, f)
+		c.timers = append(c.timers, timer)
+		return timer
+	}
+}
+
+func newLeveledLogger(levels *LevelController) (*slog.Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	return New(Config{Writer: &buf, Format: string(FormatText), Levels: levels}), &buf
+}
+
+func TestLevelControllerChangesLevelsAtRuntime(t *testing.T) {
+	levels := NewLevelController("info")
+	logger, buf := newLeveledLogger(levels)
+
+	logger.Debug("hidden")
+	if buf.Len() != 0 {
+		t.Fatalf("expected debug to be filtered at info, got %q", buf.String())
+	}
+	levels.Set(slog.LevelDebug, nil, 0)
+	logger.Debug("shown")
+	if !strings.Contains(buf.String(), "msg=shown") {
+		t.Fatalf("expected debug after lowering the level, got %q", buf.String())
+	}
+	buf.Reset()
+	levels.Set(slog.LevelError, nil, 0)
+	logger.Warn("hidden")
+	if buf.Len() != 0 {
+		t.Fatalf("expected warn to be filtered at error, got %q", buf.String())
+	}
+	if state := levels.Reset(); state.Level != slog.LevelInfo || levels.StartupLevel() != slog.LevelInfo {
+		t.Fatalf("expected reset to restore info, got %+v", state)
+	}
+}
+
+func TestLevelControllerComponentOverridesTakePrecedence(t *testing.T) {
+	levels := NewLevelController("info")
+	logger, buf := newLeveledLogger(levels)
+	ingest := WithComponent(logger, "ingest")
+	api := WithComponent(logger, "api")
+	// A component logger created before the override still follows it.
+	nested := ingest.With("channel_id", "c1")
+
+	state := levels.Set(slog.LevelWarn, map[string]slog.Level{"ingest": slog.LevelDebug}, 0)
+	if state.Components["ingest"] != slog.LevelDebug || len(state.Components) != 1 {
+		t.Fatalf("unexpected state %+v", state)
+	}
+	ingest.Debug("ingest debug")
+	nested.Debug("nested debug")
+	api.Info("api info")
+	logger.Info("root info")
+	output := buf.String()
+	if !strings.Contains(output, "ingest debug") || !strings.Contains(output, "nested debug") {
+		t.Fatalf("expected the ingest override to allow debug, got %q", output)
+	}
+	if strings.Contains(output, "api info") || strings.Contains(output, "root info") {
+		t.Fatalf("expected other loggers to follow the global warn level, got %q", output)
+	}
+
+	buf.Reset()
+	levels.Set(slog.LevelDebug, map[string]slog.Level{"ingest": slog.LevelError}, 0)
+	ingest.Warn("ingest warn")
+	api.Debug("api debug")
+	output = buf.String()
+	if strings.Contains(output, "ingest warn") || !strings.Contains(output, "api debug") {
+		t.Fatalf("expected the override to win over a lower global level, got %q", output)
+	}
+
+	buf.Reset()
+	levels.Set(slog.LevelInfo, nil, 0)
+	ingest.Debug("cleared")
+	if buf.Len() != 0 || len(levels.State().Components) != 0 {
+		t.Fatalf("expected omitted overrides to be cleared, got %q", buf.String())
+	}
+}
+
+func TestLevelControllerRevertsAfterDuration(t *testing.T) {
+	levels := NewLevelController("warn")
+	clock := &fakeRevertClock{now: time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)}
+	clock.install(levels)
+
+	state := levels.Set(slog.LevelDebug, map[string]slog.Level{"jobs": slog.LevelDebug}, 30*time.Minute)
+	if want := clock.now.Add(30 * time.Minute); !state.RevertAt.Equal(want) {
+		t.Fatalf("expected revert at %s, got %s", want, state.RevertAt)
+	}
+	if len(clock.pending) != 1 {
+		t.Fatalf("expected one scheduled revert, got %d", len(clock.pending))
+	}
+	clock.pending[0]()
+	state = levels.State()
+	if state.Level != slog.LevelWarn || len(state.Components) != 0 || !state.RevertAt.IsZero() {
+		t.Fatalf("expected the startup levels after the revert, got %+v", state)
+	}
+
+	// A later change cancels the earlier revert, which no longer applies
+	// if its timer fires anyway.
+	levels.Set(slog.LevelDebug, nil, time.Hour)
+	levels.Set(slog.LevelError, nil, 0)
+	if !clock.timers[1].stopped {
+		t.Fatal("expected the superseded revert to be stopped")
+	}
+	clock.pending[1]()
+	if got := levels.State().Level; got != slog.LevelError {
+		t.Fatalf("expected the stale revert to be ignored, got %s", got)
+	}
+}
+
+func TestLeveledHandlerFiltersWithoutAllocating(t *testing.T) {
+	levels := NewLevelController("info")
+	logger, _ := newLeveledLogger(levels)
+	component := WithComponent(logger, "chat-worker")
+	levels.Set(slog.LevelInfo, map[string]slog.Level{"ingest": slog.LevelDebug}, 0)
+
+	allocs := testing.AllocsPerRun(1000, func() {
+		component.Debug("filtered")
+	})
+	if allocs != 0 {
+		t.Fatalf("expected filtered records to cost no allocations, got %.1f", allocs)
+	}
+}
+
+func TestParseLevelRejectsUnknownNames(t *testing.T) {
+	if level, err := ParseLevel(" Warning "); err != nil || level != slog.LevelWarn {
+		t.Fatalf("expected warn, got %v (%v)", level, err)
+	}
+	if _, err := ParseLevel("verbose"); err == nil {
+		t.Fatal("expected an error for an unknown level")
+	}
+	if got := LevelName(slog.LevelWarn); got != "warn" {
+		t.Fatalf("expected warn, got %q", got)
+	}
+}
@@ -16,6 +16,10 @@ type Config struct {
 	Level  string
 	Writer io.Writer
 	Format string
+	// Levels, when set, decides which records are written so the levels
+	// can be changed at runtime, and Level is ignored. Otherwise New
+	// creates a controller starting at Level.
+	Levels *LevelController
 }
 
 type LogFormat string
@@ -44,13 +48,21 @@ func New(cfg Config) *slog.Logger {
 }
 
 func newHandler(cfg Config, writer io.Writer) slog.Handler {
-	options := &slog.HandlerOptions{Level: parseLevel(cfg.Level)}
+	levels := cfg.Levels
+	if levels == nil {
+		levels = NewLevelController(cfg.Level)
+	}
+	// The leveled handler does the filtering, so the inner handler accepts
+	// everything down to debug, the lowest level that can be configured.
+	options := &slog.HandlerOptions{Level: slog.LevelDebug}
+	var inner slog.Handler
 	switch LogFormat(strings.ToLower(strings.TrimSpace(cfg.Format))) {
 	case FormatText:
-		return slog.NewTextHandler(writer, options)
+		inner = slog.NewTextHandler(writer, options)
 	default:
-		return slog.NewJSONHandler(writer, options)
+		inner = slog.NewJSONHandler(writer, options)
 	}
+	return &leveledHandler{inner: inner, levels: levels}
 }
 
 func parseLevel(level string) slog.Leveler {
@@ -73,11 +85,13 @@ func parseLevel(level string) slog.Leveler {
 }
 
 // WithComponent returns a logger annotated with the provided component field.
+// Loggers built by New resolve the component's level override here, once,
+// rather than on every record.
 func WithComponent(logger *slog.Logger, component string) *slog.Logger {
 	if logger == nil {
 		return nil
 	}
-	return logger.With("component", component)
+	return logger.With(componentKey, component)
 }
 
 type contextKey string
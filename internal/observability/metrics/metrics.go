@@ -43,6 +43,23 @@ type Recorder struct {
 	ingestFailures    map[string]uint64
 	transcoderEvents  map[TranscoderJobLabel]uint64
 	activeTranscoder  atomic.Int64
+	transcoderDisk    map[string]uint64
+	objectOps         map[string]uint64
+	objectDuration    map[string]time.Duration
+	objectRetries     map[string]uint64
+	cacheLookups      map[CacheLookupLabel]uint64
+	tlsReloads        map[string]uint64
+	mailMessages      map[string]uint64
+	conditionals      map[ConditionalResponseLabel]uint64
+	datastoreTx       map[DatastoreTxLabel]uint64
+	loginFailures     map[LoginFailureLabel]uint64
+	chatQueueDepth    atomic.Int64
+	chatQueuePending  atomic.Int64
+	chatDeadLetters   atomic.Int64
+	chatBatches       uint64
+	chatBatchEvents   uint64
+	chatBatchFailed   uint64
+	chatBatchDuration time.Duration
 }
 
 type TranscoderJobLabel struct {
@@ -50,6 +67,38 @@ type TranscoderJobLabel struct {
 	Status string
 }
 
+// CacheLookupLabel identifies a read cache namespace and whether the lookup
+// was served from the cache ("hit") or loaded from storage ("miss").
+type CacheLookupLabel struct {
+	Namespace string
+	Result    string
+}
+
+// ConditionalResponseLabel identifies an endpoint that supports conditional
+// GETs and the status it answered with: "200" for a full body or "304" when
+// the client's ETag still matched.
+type ConditionalResponseLabel struct {
+	Endpoint string
+	Status   string
+}
+
+// DatastoreTxLabel identifies a datastore transaction and what happened to
+// it: "retry" when a serialization failure or deadlock reran it, "timeout"
+// when a statement hit the statement timeout.
+type DatastoreTxLabel struct {
+	Operation string
+	Event     string
+}
+
+// LoginFailureLabel identifies a failed sign-in by the class of address it
+// came from ("loopback", "private", "public", or "unknown") and why it
+// failed. Addresses are classed rather than listed to keep the series
+// bounded; the audit log has the addresses themselves.
+type LoginFailureLabel struct {
+	IPClass string
+	Reason  string
+}
+
 var defaultRecorder = New()
 
 // SetDefault swaps the package-level recorder used by helper functions and the
@@ -84,6 +133,16 @@ func New() *Recorder {
 		ingestAttempts:    make(map[string]uint64),
 		ingestFailures:    make(map[string]uint64),
 		transcoderEvents:  make(map[TranscoderJobLabel]uint64),
+		transcoderDisk:    make(map[string]uint64),
+		objectOps:         make(map[string]uint64),
+		objectDuration:    make(map[string]time.Duration),
+		objectRetries:     make(map[string]uint64),
+		cacheLookups:      make(map[CacheLookupLabel]uint64),
+		tlsReloads:        make(map[string]uint64),
+		mailMessages:      make(map[string]uint64),
+		conditionals:      make(map[ConditionalResponseLabel]uint64),
+		datastoreTx:       make(map[DatastoreTxLabel]uint64),
+		loginFailures:     make(map[LoginFailureLabel]uint64),
 	}
 }
 
@@ -161,6 +220,39 @@ func (r *Recorder) ObserveChatEvent(event string) {
 	r.mu.Unlock()
 }
 
+// SetChatQueueStats records the chat queue backlog: events waiting for a
+// consumer, events delivered but not yet acknowledged, and dead-lettered events.
+func (r *Recorder) SetChatQueueStats(depth, pending, deadLetters int64) {
+	r.chatQueueDepth.Store(depth)
+	r.chatQueuePending.Store(pending)
+	r.chatDeadLetters.Store(deadLetters)
+}
+
+// ChatQueueStats returns the most recently recorded chat queue gauges.
+func (r *Recorder) ChatQueueStats() (depth, pending, deadLetters int64) {
+	return r.chatQueueDepth.Load(), r.chatQueuePending.Load(), r.chatDeadLetters.Load()
+}
+
+// ObserveChatBatch records a batch of chat events the chat worker wrote to
+// storage: how many events it held, how many of them failed, and how long
+// the write took.
+func (r *Recorder) ObserveChatBatch(size, failed int, duration time.Duration) {
+	r.mu.Lock()
+	r.chatBatches++
+	r.chatBatchEvents += uint64(size)
+	r.chatBatchFailed += uint64(failed)
+	r.chatBatchDuration += duration
+	r.mu.Unlock()
+}
+
+// ChatBatchStats returns the chat worker's batch totals: batches written,
+// events they held, events that failed, and the time spent writing them.
+func (r *Recorder) ChatBatchStats() (batches, events, failed uint64, duration time.Duration) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.chatBatches, r.chatBatchEvents, r.chatBatchFailed, r.chatBatchDuration
+}
+
 // ObserveMonetization tracks monetization events, capturing counts and total amounts.
 func (r *Recorder) ObserveMonetization(event string, amount models.Money) {
 	normalized := strings.ToLower(strings.TrimSpace(event))
@@ -174,6 +266,78 @@ func (r *Recorder) ObserveMonetization(event string, amount models.Money) {
 	r.mu.Unlock()
 }
 
+// ObserveObjectStorage records a completed object storage operation (for
+// example "upload", "multipart_upload", or "delete") and how long it took,
+// including any retries.
+func (r *Recorder) ObserveObjectStorage(operation string, duration time.Duration) {
+	normalized := normalizeName(operation)
+	r.mu.Lock()
+	r.objectOps[normalized]++
+	r.objectDuration[normalized] += duration
+	r.mu.Unlock()
+}
+
+// ObserveObjectStorageRetry records a retried object storage request.
+func (r *Recorder) ObserveObjectStorageRetry(operation string) {
+	normalized := normalizeName(operation)
+	r.mu.Lock()
+	r.objectRetries[normalized]++
+	r.mu.Unlock()
+}
+
+// ObserveCacheLookup records a read cache lookup for the namespace with the
+// given result, typically "hit" or "miss".
+func (r *Recorder) ObserveCacheLookup(namespace, result string) {
+	label := CacheLookupLabel{Namespace: normalizeName(namespace), Result: normalizeName(result)}
+	r.mu.Lock()
+	r.cacheLookups[label]++
+	r.mu.Unlock()
+}
+
+// ObserveConditionalResponse records a response from an endpoint that
+// supports conditional GETs with its status code.
+func (r *Recorder) ObserveConditionalResponse(endpoint string, status int) {
+	label := ConditionalResponseLabel{Endpoint: normalizeName(endpoint), Status: fmt.Sprintf("%d", status)}
+	r.mu.Lock()
+	r.conditionals[label]++
+	r.mu.Unlock()
+}
+
+// ObserveDatastoreTx records a retry or timeout of the named datastore
+// transaction.
+func (r *Recorder) ObserveDatastoreTx(operation, event string) {
+	label := DatastoreTxLabel{Operation: normalizeName(operation), Event: normalizeName(event)}
+	r.mu.Lock()
+	r.datastoreTx[label]++
+	r.mu.Unlock()
+}
+
+// ObserveLoginFailure records a failed sign-in from an address of ipClass.
+func (r *Recorder) ObserveLoginFailure(ipClass, reason string) {
+	label := LoginFailureLabel{IPClass: normalizeName(ipClass), Reason: normalizeName(reason)}
+	r.mu.Lock()
+	r.loginFailures[label]++
+	r.mu.Unlock()
+}
+
+// ObserveTLSReload records an attempt to reload the serving certificate with
+// its result, either "swapped" or "rejected".
+func (r *Recorder) ObserveTLSReload(result string) {
+	result = normalizeName(result)
+	r.mu.Lock()
+	r.tlsReloads[result]++
+	r.mu.Unlock()
+}
+
+// ObserveMail records what happened to an outbound email: "queued",
+// "sent", "retried", "failed", "dropped", or "rate_limited".
+func (r *Recorder) ObserveMail(result string) {
+	result = normalizeName(result)
+	r.mu.Lock()
+	r.mailMessages[result]++
+	r.mu.Unlock()
+}
+
 // TranscoderJobStarted records the beginning of a transcoder job of the
 // provided kind (e.g., "live" or "upload") and increments the active job
 // gauge.
@@ -207,6 +371,27 @@ func (r *Recorder) recordTranscoderEvent(kind, status string) {
 	r.mu.Unlock()
 }
 
+// SetTranscoderDiskFree records the free bytes on a transcoder volume, such
+// as "output" or "public".
+func (r *Recorder) SetTranscoderDiskFree(volume string, free uint64) {
+	normalized := normalizeName(volume)
+	r.mu.Lock()
+	r.transcoderDisk[normalized] = free
+	r.mu.Unlock()
+}
+
+// TranscoderDiskFree returns a copy of the most recent free bytes per
+// transcoder volume.
+func (r *Recorder) TranscoderDiskFree() map[string]uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	out := make(map[string]uint64, len(r.transcoderDisk))
+	for volume, free := range r.transcoderDisk {
+		out[volume] = free
+	}
+	return out
+}
+
 // ActiveStreams exposes the current gauge of concurrently active streams.
 func (r *Recorder) ActiveStreams() int64 {
 	return r.activeStreams.Load()
@@ -257,6 +442,90 @@ func (r *Recorder) IngestCounts() (attempts map[string]uint64, failures map[stri
 	return attempts, failures
 }
 
+// ObjectStorageCounts returns copies of the object storage operation and retry
+// counters for testing and reporting purposes.
+func (r *Recorder) ObjectStorageCounts() (operations map[string]uint64, retries map[string]uint64) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	operations = make(map[string]uint64, len(r.objectOps))
+	for k, v := range r.objectOps {
+		operations[k] = v
+	}
+	retries = make(map[string]uint64, len(r.objectRetries))
+	for k, v := range r.objectRetries {
+		retries[k] = v
+	}
+	return operations, retries
+}
+
+// CacheLookupCounts returns a copy of the read cache lookup counters.
+func (r *Recorder) CacheLookupCounts() map[CacheLookupLabel]uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	counts := make(map[CacheLookupLabel]uint64, len(r.cacheLookups))
+	for k, v := range r.cacheLookups {
+		counts[k] = v
+	}
+	return counts
+}
+
+// ConditionalResponseCounts returns a copy of the conditional GET response
+// counters.
+func (r *Recorder) ConditionalResponseCounts() map[ConditionalResponseLabel]uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	counts := make(map[ConditionalResponseLabel]uint64, len(r.conditionals))
+	for k, v := range r.conditionals {
+		counts[k] = v
+	}
+	return counts
+}
+
+// DatastoreTxCounts returns a copy of the datastore transaction retry and
+// timeout counters.
+func (r *Recorder) DatastoreTxCounts() map[DatastoreTxLabel]uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	counts := make(map[DatastoreTxLabel]uint64, len(r.datastoreTx))
+	for k, v := range r.datastoreTx {
+		counts[k] = v
+	}
+	return counts
+}
+
+// LoginFailureCounts returns a copy of the failed sign-in counters.
+func (r *Recorder) LoginFailureCounts() map[LoginFailureLabel]uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	counts := make(map[LoginFailureLabel]uint64, len(r.loginFailures))
+	for k, v := range r.loginFailures {
+		counts[k] = v
+	}
+	return counts
+}
+
+// TLSReloadCounts returns a copy of the certificate reload counters by result.
+func (r *Recorder) TLSReloadCounts() map[string]uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	counts := make(map[string]uint64, len(r.tlsReloads))
+	for k, v := range r.tlsReloads {
+		counts[k] = v
+	}
+	return counts
+}
+
+// MailCounts returns a copy of the outbound email counters by result.
+func (r *Recorder) MailCounts() map[string]uint64 {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	counts := make(map[string]uint64, len(r.mailMessages))
+	for k, v := range r.mailMessages {
+		counts[k] = v
+	}
+	return counts
+}
+
 // TranscoderJobCounts returns copies of transcoder job event counters and the
 // current active job gauge value.
 func (r *Recorder) TranscoderJobCounts() (events map[TranscoderJobLabel]uint64, active int64) {
@@ -285,8 +554,25 @@ func (r *Recorder) Reset() {
 	r.ingestAttempts = make(map[string]uint64)
 	r.ingestFailures = make(map[string]uint64)
 	r.transcoderEvents = make(map[TranscoderJobLabel]uint64)
+	r.transcoderDisk = make(map[string]uint64)
+	r.objectOps = make(map[string]uint64)
+	r.objectDuration = make(map[string]time.Duration)
+	r.objectRetries = make(map[string]uint64)
+	r.cacheLookups = make(map[CacheLookupLabel]uint64)
+	r.tlsReloads = make(map[string]uint64)
+	r.mailMessages = make(map[string]uint64)
+	r.conditionals = make(map[ConditionalResponseLabel]uint64)
+	r.datastoreTx = make(map[DatastoreTxLabel]uint64)
+	r.loginFailures = make(map[LoginFailureLabel]uint64)
 	r.activeStreams.Store(0)
 	r.activeTranscoder.Store(0)
+	r.chatQueueDepth.Store(0)
+	r.chatQueuePending.Store(0)
+	r.chatDeadLetters.Store(0)
+	r.chatBatches = 0
+	r.chatBatchEvents = 0
+	r.chatBatchFailed = 0
+	r.chatBatchDuration = 0
 }
 
 // Handler exposes the Registry's recorder as an http.Handler.
@@ -330,6 +616,13 @@ func (r *Recorder) Write(w io.Writer) {
 	monetizationEvents := r.sortedMonetizationEvents()
 	ingestOperations := r.sortedIngestOperations()
 	transcoderEvents := r.sortedTranscoderJobLabels()
+	objectOperations := r.sortedObjectStorageOperations()
+	cacheLabels := r.sortedCacheLookupLabels()
+	conditionalLabels := r.sortedConditionalResponseLabels()
+	datastoreTxLabels := r.sortedDatastoreTxLabels()
+	loginFailureLabels := r.sortedLoginFailureLabels()
+	tlsReloadResults := r.sortedTLSReloadResults()
+	mailResults := r.sortedMailResults()
 
 	_, _ = fmt.Fprintln(w, "# HELP bitriver_http_requests_total Total number of HTTP requests processed by the API")
 	_, _ = fmt.Fprintln(w, "# TYPE bitriver_http_requests_total counter")
@@ -392,6 +685,34 @@ func (r *Recorder) Write(w io.Writer) {
 		_, _ = fmt.Fprintf(w, "bitriver_chat_events_total{event=\"%s\"} %d\n", event, count)
 	}
 
+	_, _ = fmt.Fprintln(w, "# HELP bitriver_chat_queue_depth Chat events waiting to be delivered to the chat worker")
+	_, _ = fmt.Fprintln(w, "# TYPE bitriver_chat_queue_depth gauge")
+	_, _ = fmt.Fprintf(w, "bitriver_chat_queue_depth %d\n", r.chatQueueDepth.Load())
+
+	_, _ = fmt.Fprintln(w, "# HELP bitriver_chat_queue_pending Chat events delivered to the chat worker but not yet acknowledged")
+	_, _ = fmt.Fprintln(w, "# TYPE bitriver_chat_queue_pending gauge")
+	_, _ = fmt.Fprintf(w, "bitriver_chat_queue_pending %d\n", r.chatQueuePending.Load())
+
+	_, _ = fmt.Fprintln(w, "# HELP bitriver_chat_queue_dead_letters Chat events moved to the dead-letter queue after repeated failures")
+	_, _ = fmt.Fprintln(w, "# TYPE bitriver_chat_queue_dead_letters gauge")
+	_, _ = fmt.Fprintf(w, "bitriver_chat_queue_dead_letters %d\n", r.chatDeadLetters.Load())
+
+	_, _ = fmt.Fprintln(w, "# HELP bitriver_chat_batches_total Batches of chat events the chat worker wrote to storage")
+	_, _ = fmt.Fprintln(w, "# TYPE bitriver_chat_batches_total counter")
+	_, _ = fmt.Fprintf(w, "bitriver_chat_batches_total %d\n", r.chatBatches)
+
+	_, _ = fmt.Fprintln(w, "# HELP bitriver_chat_batch_events_total Chat events written in batches; divide by bitriver_chat_batches_total for the mean batch size")
+	_, _ = fmt.Fprintln(w, "# TYPE bitriver_chat_batch_events_total counter")
+	_, _ = fmt.Fprintf(w, "bitriver_chat_batch_events_total %d\n", r.chatBatchEvents)
+
+	_, _ = fmt.Fprintln(w, "# HELP bitriver_chat_batch_failed_events_total Chat events in batches that could not be stored")
+	_, _ = fmt.Fprintln(w, "# TYPE bitriver_chat_batch_failed_events_total counter")
+	_, _ = fmt.Fprintf(w, "bitriver_chat_batch_failed_events_total %d\n", r.chatBatchFailed)
+
+	_, _ = fmt.Fprintln(w, "# HELP bitriver_chat_batch_flush_seconds_sum Cumulative time spent writing chat event batches in seconds")
+	_, _ = fmt.Fprintln(w, "# TYPE bitriver_chat_batch_flush_seconds_sum counter")
+	_, _ = fmt.Fprintf(w, "bitriver_chat_batch_flush_seconds_sum %f\n", r.chatBatchDuration.Seconds())
+
 	_, _ = fmt.Fprintln(w, "# HELP bitriver_transcoder_jobs_total Transcoder job events by type and status")
 	_, _ = fmt.Fprintln(w, "# TYPE bitriver_transcoder_jobs_total counter")
 	for _, label := range transcoderEvents {
@@ -403,6 +724,66 @@ func (r *Recorder) Write(w io.Writer) {
 	_, _ = fmt.Fprintln(w, "# TYPE bitriver_transcoder_active_jobs gauge")
 	_, _ = fmt.Fprintf(w, "bitriver_transcoder_active_jobs %d\n", r.activeTranscoder.Load())
 
+	_, _ = fmt.Fprintln(w, "# HELP bitriver_transcoder_disk_free_bytes Free bytes on each transcoder volume")
+	_, _ = fmt.Fprintln(w, "# TYPE bitriver_transcoder_disk_free_bytes gauge")
+	for _, volume := range r.sortedTranscoderVolumes() {
+		_, _ = fmt.Fprintf(w, "bitriver_transcoder_disk_free_bytes{volume=\"%s\"} %d\n", volume, r.transcoderDisk[volume])
+	}
+
+	_, _ = fmt.Fprintln(w, "# HELP bitriver_object_storage_operations_total Completed object storage operations by type")
+	_, _ = fmt.Fprintln(w, "# TYPE bitriver_object_storage_operations_total counter")
+	for _, op := range objectOperations {
+		_, _ = fmt.Fprintf(w, "bitriver_object_storage_operations_total{operation=\"%s\"} %d\n", op, r.objectOps[op])
+	}
+
+	_, _ = fmt.Fprintln(w, "# HELP bitriver_object_storage_duration_seconds_sum Cumulative duration of object storage operations in seconds")
+	_, _ = fmt.Fprintln(w, "# TYPE bitriver_object_storage_duration_seconds_sum counter")
+	for _, op := range objectOperations {
+		_, _ = fmt.Fprintf(w, "bitriver_object_storage_duration_seconds_sum{operation=\"%s\"} %f\n", op, r.objectDuration[op].Seconds())
+	}
+
+	_, _ = fmt.Fprintln(w, "# HELP bitriver_object_storage_retries_total Object storage requests retried after transient failures")
+	_, _ = fmt.Fprintln(w, "# TYPE bitriver_object_storage_retries_total counter")
+	for _, op := range objectOperations {
+		_, _ = fmt.Fprintf(w, "bitriver_object_storage_retries_total{operation=\"%s\"} %d\n", op, r.objectRetries[op])
+	}
+
+	_, _ = fmt.Fprintln(w, "# HELP bitriver_read_cache_lookups_total Read cache lookups by namespace and result (hit or miss)")
+	_, _ = fmt.Fprintln(w, "# TYPE bitriver_read_cache_lookups_total counter")
+	for _, label := range cacheLabels {
+		_, _ = fmt.Fprintf(w, "bitriver_read_cache_lookups_total{namespace=\"%s\",result=\"%s\"} %d\n", label.Namespace, label.Result, r.cacheLookups[label])
+	}
+
+	_, _ = fmt.Fprintln(w, "# HELP bitriver_http_conditional_responses_total Responses from ETag-enabled endpoints by status (200 full body or 304 not modified)")
+	_, _ = fmt.Fprintln(w, "# TYPE bitriver_http_conditional_responses_total counter")
+	for _, label := range conditionalLabels {
+		_, _ = fmt.Fprintf(w, "bitriver_http_conditional_responses_total{endpoint=\"%s\",status=\"%s\"} %d\n", label.Endpoint, label.Status, r.conditionals[label])
+	}
+
+	_, _ = fmt.Fprintln(w, "# HELP bitriver_datastore_transaction_events_total Datastore transactions retried after a serialization failure or deadlock, or cancelled by the statement timeout")
+	_, _ = fmt.Fprintln(w, "# TYPE bitriver_datastore_transaction_events_total counter")
+	for _, label := range datastoreTxLabels {
+		_, _ = fmt.Fprintf(w, "bitriver_datastore_transaction_events_total{operation=\"%s\",event=\"%s\"} %d\n", label.Operation, label.Event, r.datastoreTx[label])
+	}
+
+	_, _ = fmt.Fprintln(w, "# HELP bitriver_auth_login_failures_total Failed sign-ins by source address class and failure reason")
+	_, _ = fmt.Fprintln(w, "# TYPE bitriver_auth_login_failures_total counter")
+	for _, label := range loginFailureLabels {
+		_, _ = fmt.Fprintf(w, "bitriver_auth_login_failures_total{ip_class=\"%s\",reason=\"%s\"} %d\n", label.IPClass, label.Reason, r.loginFailures[label])
+	}
+
+	_, _ = fmt.Fprintln(w, "# HELP bitriver_tls_certificate_reloads_total Serving certificate reloads by result (swapped or rejected)")
+	_, _ = fmt.Fprintln(w, "# TYPE bitriver_tls_certificate_reloads_total counter")
+	for _, result := range tlsReloadResults {
+		_, _ = fmt.Fprintf(w, "bitriver_tls_certificate_reloads_total{result=\"%s\"} %d\n", result, r.tlsReloads[result])
+	}
+
+	_, _ = fmt.Fprintln(w, "# HELP bitriver_mail_messages_total Outbound emails by result (queued, sent, retried, failed, dropped, or rate_limited)")
+	_, _ = fmt.Fprintln(w, "# TYPE bitriver_mail_messages_total counter")
+	for _, result := range mailResults {
+		_, _ = fmt.Fprintf(w, "bitriver_mail_messages_total{result=\"%s\"} %d\n", result, r.mailMessages[result])
+	}
+
 	_, _ = fmt.Fprintln(w, "# HELP bitriver_monetization_events_total Monetization events by type")
 	_, _ = fmt.Fprintln(w, "# TYPE bitriver_monetization_events_total counter")
 	for _, event := range monetizationEvents {
@@ -500,6 +881,105 @@ func (r *Recorder) sortedIngestOperations() []string {
 	return ops
 }
 
+func (r *Recorder) sortedTranscoderVolumes() []string {
+	volumes := make([]string, 0, len(r.transcoderDisk))
+	for volume := range r.transcoderDisk {
+		volumes = append(volumes, volume)
+	}
+	sort.Strings(volumes)
+	return volumes
+}
+
+func (r *Recorder) sortedObjectStorageOperations() []string {
+	seen := make(map[string]struct{}, len(r.objectOps)+len(r.objectRetries))
+	for op := range r.objectOps {
+		seen[op] = struct{}{}
+	}
+	for op := range r.objectRetries {
+		seen[op] = struct{}{}
+	}
+	ops := make([]string, 0, len(seen))
+	for op := range seen {
+		ops = append(ops, op)
+	}
+	sort.Strings(ops)
+	return ops
+}
+
+func (r *Recorder) sortedCacheLookupLabels() []CacheLookupLabel {
+	labels := make([]CacheLookupLabel, 0, len(r.cacheLookups))
+	for label := range r.cacheLookups {
+		labels = append(labels, label)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if labels[i].Namespace != labels[j].Namespace {
+			return labels[i].Namespace < labels[j].Namespace
+		}
+		return labels[i].Result < labels[j].Result
+	})
+	return labels
+}
+
+func (r *Recorder) sortedConditionalResponseLabels() []ConditionalResponseLabel {
+	labels := make([]ConditionalResponseLabel, 0, len(r.conditionals))
+	for label := range r.conditionals {
+		labels = append(labels, label)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if labels[i].Endpoint != labels[j].Endpoint {
+			return labels[i].Endpoint < labels[j].Endpoint
+		}
+		return labels[i].Status < labels[j].Status
+	})
+	return labels
+}
+
+func (r *Recorder) sortedDatastoreTxLabels() []DatastoreTxLabel {
+	labels := make([]DatastoreTxLabel, 0, len(r.datastoreTx))
+	for label := range r.datastoreTx {
+		labels = append(labels, label)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if labels[i].Operation != labels[j].Operation {
+			return labels[i].Operation < labels[j].Operation
+		}
+		return labels[i].Event < labels[j].Event
+	})
+	return labels
+}
+
+func (r *Recorder) sortedLoginFailureLabels() []LoginFailureLabel {
+	labels := make([]LoginFailureLabel, 0, len(r.loginFailures))
+	for label := range r.loginFailures {
+		labels = append(labels, label)
+	}
+	sort.Slice(labels, func(i, j int) bool {
+		if labels[i].IPClass != labels[j].IPClass {
+			return labels[i].IPClass < labels[j].IPClass
+		}
+		return labels[i].Reason < labels[j].Reason
+	})
+	return labels
+}
+
+func (r *Recorder) sortedTLSReloadResults() []string {
+	results := make([]string, 0, len(r.tlsReloads))
+	for result := range r.tlsReloads {
+		results = append(results, result)
+	}
+	sort.Strings(results)
+	return results
+}
+
+func (r *Recorder) sortedMailResults() []string {
+	results := make([]string, 0, len(r.mailMessages))
+	for result := range r.mailMessages {
+		results = append(results, result)
+	}
+	sort.Strings(results)
+	return results
+}
+
 func (r *Recorder) sortedTranscoderJobLabels() []TranscoderJobLabel {
 	labels := make([]TranscoderJobLabel, 0, len(r.transcoderEvents))
 	for label := range r.transcoderEvents {
@@ -616,6 +1096,11 @@ func TranscoderJobFailed(kind string) {
 	defaultRecorder.TranscoderJobFailed(kind)
 }
 
+// SetTranscoderDiskFree records transcoder free space on the default recorder.
+func SetTranscoderDiskFree(volume string, free uint64) {
+	defaultRecorder.SetTranscoderDiskFree(volume, free)
+}
+
 // Handler exposes the default recorder as an HTTP handler.
 func Handler() http.Handler {
 	return defaultRecorder.Handler()
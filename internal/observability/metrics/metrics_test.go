@@ -177,17 +177,45 @@ func TestWriteAndHandlerOutput(t *testing.T) {
 	recorder.TranscoderJobStarted("upload")
 	recorder.TranscoderJobFailed("upload")
 	recorder.TranscoderJobStarted("upload")
+	recorder.SetTranscoderDiskFree("public", 2048)
+	recorder.SetTranscoderDiskFree("output", 1024)
 
 	recorder.SetIngestHealth(" Ingest-A ", "Healthy")
 	recorder.SetIngestHealth("backup", "Degraded")
 
 	recorder.ObserveChatEvent("message")
 	recorder.ObserveChatEvent("message")
+	recorder.SetChatQueueStats(4, 2, 1)
+	recorder.ObserveChatBatch(3, 0, 20*time.Millisecond)
+	recorder.ObserveChatBatch(2, 1, 30*time.Millisecond)
 
 	recorder.ObserveMonetization("tip", models.MustParseMoney("1.5"))
 	recorder.ObserveMonetization("tip", models.MustParseMoney("0.25"))
 	recorder.ObserveMonetization("subscription", models.MustParseMoney("10"))
 
+	recorder.ObserveObjectStorage("upload", 500*time.Millisecond)
+	recorder.ObserveObjectStorage("upload", 250*time.Millisecond)
+	recorder.ObserveObjectStorageRetry("upload")
+
+	recorder.ObserveCacheLookup("directory", "miss")
+	recorder.ObserveCacheLookup("directory", "hit")
+	recorder.ObserveCacheLookup("directory", "hit")
+	recorder.ObserveConditionalResponse("directory", 200)
+	recorder.ObserveConditionalResponse("directory", 304)
+	recorder.ObserveConditionalResponse("directory", 304)
+	recorder.ObserveDatastoreTx("start stream", "retry")
+	recorder.ObserveDatastoreTx("start stream", "retry")
+	recorder.ObserveDatastoreTx("delete user", "timeout")
+	recorder.ObserveLoginFailure("public", "invalid_credentials")
+	recorder.ObserveLoginFailure("public", "invalid_credentials")
+	recorder.ObserveLoginFailure("private", "account_deactivated")
+	recorder.ObserveTLSReload("swapped")
+	recorder.ObserveTLSReload("rejected")
+	recorder.ObserveTLSReload("swapped")
+	recorder.ObserveMail("sent")
+	recorder.ObserveMail("failed")
+	recorder.ObserveMail("sent")
+
 	var buf bytes.Buffer
 	recorder.Write(&buf)
 
@@ -225,6 +253,27 @@ bitriver_ingest_failures_total{operation="upload_transcode"} 1
 # HELP bitriver_chat_events_total Chat events by type
 # TYPE bitriver_chat_events_total counter
 bitriver_chat_events_total{event="message"} 2
+# HELP bitriver_chat_queue_depth Chat events waiting to be delivered to the chat worker
+# TYPE bitriver_chat_queue_depth gauge
+bitriver_chat_queue_depth 4
+# HELP bitriver_chat_queue_pending Chat events delivered to the chat worker but not yet acknowledged
+# TYPE bitriver_chat_queue_pending gauge
+bitriver_chat_queue_pending 2
+# HELP bitriver_chat_queue_dead_letters Chat events moved to the dead-letter queue after repeated failures
+# TYPE bitriver_chat_queue_dead_letters gauge
+bitriver_chat_queue_dead_letters 1
+# HELP bitriver_chat_batches_total Batches of chat events the chat worker wrote to storage
+# TYPE bitriver_chat_batches_total counter
+bitriver_chat_batches_total 2
+# HELP bitriver_chat_batch_events_total Chat events written in batches; divide by bitriver_chat_batches_total for the mean batch size
+# TYPE bitriver_chat_batch_events_total counter
+bitriver_chat_batch_events_total 5
+# HELP bitriver_chat_batch_failed_events_total Chat events in batches that could not be stored
+# TYPE bitriver_chat_batch_failed_events_total counter
+bitriver_chat_batch_failed_events_total 1
+# HELP bitriver_chat_batch_flush_seconds_sum Cumulative time spent writing chat event batches in seconds
+# TYPE bitriver_chat_batch_flush_seconds_sum counter
+bitriver_chat_batch_flush_seconds_sum 0.050000
 # HELP bitriver_transcoder_jobs_total Transcoder job events by type and status
 # TYPE bitriver_transcoder_jobs_total counter
 bitriver_transcoder_jobs_total{kind="live",status="complete"} 1
@@ -234,6 +283,43 @@ bitriver_transcoder_jobs_total{kind="upload",status="start"} 2
 # HELP bitriver_transcoder_active_jobs Current number of active transcoder jobs
 # TYPE bitriver_transcoder_active_jobs gauge
 bitriver_transcoder_active_jobs 1
+# HELP bitriver_transcoder_disk_free_bytes Free bytes on each transcoder volume
+# TYPE bitriver_transcoder_disk_free_bytes gauge
+bitriver_transcoder_disk_free_bytes{volume="output"} 1024
+bitriver_transcoder_disk_free_bytes{volume="public"} 2048
+# HELP bitriver_object_storage_operations_total Completed object storage operations by type
+# TYPE bitriver_object_storage_operations_total counter
+bitriver_object_storage_operations_total{operation="upload"} 2
+# HELP bitriver_object_storage_duration_seconds_sum Cumulative duration of object storage operations in seconds
+# TYPE bitriver_object_storage_duration_seconds_sum counter
+bitriver_object_storage_duration_seconds_sum{operation="upload"} 0.750000
+# HELP bitriver_object_storage_retries_total Object storage requests retried after transient failures
+# TYPE bitriver_object_storage_retries_total counter
+bitriver_object_storage_retries_total{operation="upload"} 1
+# HELP bitriver_read_cache_lookups_total Read cache lookups by namespace and result (hit or miss)
+# TYPE bitriver_read_cache_lookups_total counter
+bitriver_read_cache_lookups_total{namespace="directory",result="hit"} 2
+bitriver_read_cache_lookups_total{namespace="directory",result="miss"} 1
+# HELP bitriver_http_conditional_responses_total Responses from ETag-enabled endpoints by status (200 full body or 304 not modified)
+# TYPE bitriver_http_conditional_responses_total counter
+bitriver_http_conditional_responses_total{endpoint="directory",status="200"} 1
+bitriver_http_conditional_responses_total{endpoint="directory",status="304"} 2
+# HELP bitriver_datastore_transaction_events_total Datastore transactions retried after a serialization failure or deadlock, or cancelled by the statement timeout
+# TYPE bitriver_datastore_transaction_events_total counter
+bitriver_datastore_transaction_events_total{operation="delete user",event="timeout"} 1
+bitriver_datastore_transaction_events_total{operation="start stream",event="retry"} 2
+# HELP bitriver_auth_login_failures_total Failed sign-ins by source address class and failure reason
+# TYPE bitriver_auth_login_failures_total counter
+bitriver_auth_login_failures_total{ip_class="private",reason="account_deactivated"} 1
+bitriver_auth_login_failures_total{ip_class="public",reason="invalid_credentials"} 2
+# HELP bitriver_tls_certificate_reloads_total Serving certificate reloads by result (swapped or rejected)
+# TYPE bitriver_tls_certificate_reloads_total counter
+bitriver_tls_certificate_reloads_total{result="rejected"} 1
+bitriver_tls_certificate_reloads_total{result="swapped"} 2
+# HELP bitriver_mail_messages_total Outbound emails by result (queued, sent, retried, failed, dropped, or rate_limited)
+# TYPE bitriver_mail_messages_total counter
+bitriver_mail_messages_total{result="failed"} 1
+bitriver_mail_messages_total{result="sent"} 2
 # HELP bitriver_monetization_events_total Monetization events by type
 # TYPE bitriver_monetization_events_total counter
 bitriver_monetization_events_total{event="subscription"} 1
@@ -0,0 +1,55 @@
+package server
+
+import (
+	"net/http"
+	"strings"
+
+	"bitriver-live/internal/storage"
+)
+
+// apiTokenScope returns the scope a personal access token needs for the
+// request. Reads need the read scope. Writes are refused unless the route is
+// listed here, and each listed handler checks the same scope itself, so a new
+// write endpoint stays closed to tokens until it opts in.
+func apiTokenScope(r *http.Request) (string, bool) {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return storage.APITokenScopeRead, true
+	}
+	parts := strings.Split(strings.Trim(r.URL.Path, "/"), "/")
+	if len(parts) < 2 || parts[0] != "api" {
+		return "", false
+	}
+	switch parts[1] {
+	case "channels":
+		if len(parts) == 2 {
+			return storage.APITokenScopeManageChannel, r.Method == http.MethodPost
+		}
+		if len(parts) == 3 {
+			return storage.APITokenScopeManageChannel, r.Method == http.MethodPatch || r.Method == http.MethodDelete
+		}
+		switch parts[3] {
+		case "chat":
+			return storage.APITokenScopeChat, len(parts) == 4 && r.Method == http.MethodPost
+		case "stream":
+			// Markers are open to moderators and skip the manager check
+			// the other stream actions make.
+			return storage.APITokenScopeManageChannel, len(parts) == 5 && parts[4] != "markers"
+		case "costream", "editors", "moderators", "restreams", "schedule", "storage":
+			return storage.APITokenScopeManageChannel, true
+		}
+	case "uploads":
+		if len(parts) == 2 {
+			return storage.APITokenScopeManageChannel, r.Method == http.MethodPost
+		}
+		return storage.APITokenScopeManageChannel, len(parts) == 3 && r.Method == http.MethodDelete
+	case "recordings":
+		if len(parts) == 3 {
+			return storage.APITokenScopeManageChannel, r.Method == http.MethodDelete
+		}
+		if len(parts) == 4 && r.Method == http.MethodPost {
+			return storage.APITokenScopeManageChannel, parts[3] == "publish" || parts[3] == "clips"
+		}
+	}
+	return "", false
+}
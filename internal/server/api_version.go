@@ -0,0 +1,28 @@
+package server
+
+import (
+	"net/http"
+
+	"bitriver-live/internal/api"
+)
+
+// apiVersionMiddleware serves /api/v1/... with the handlers registered under
+// /api/. It strips the version segment so routing and the middleware inside it
+// match a single set of paths, and records the version on the request context
+// for handlers that branch on response shape. Logging, audit, and metrics wrap
+// this middleware, so they keep reporting the path as requested, version
+// included.
+func apiVersionMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		version, route := api.SplitAPIVersion(r.URL.Path)
+		ctx := api.ContextWithAPIVersion(r.Context(), version)
+		if route == r.URL.Path {
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+		routed := r.Clone(ctx)
+		routed.URL.Path = route
+		routed.URL.RawPath = ""
+		next.ServeHTTP(w, routed)
+	})
+}
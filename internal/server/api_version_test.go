@@ -0,0 +1,119 @@
+package server
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"bitriver-live/internal/api"
+	"bitriver-live/internal/observability/metrics"
+	"bitriver-live/internal/storage"
+)
+
+func TestAPIVersionMiddlewareStripsPrefix(t *testing.T) {
+	var gotPath string
+	var gotVersion api.APIVersion
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		gotPath = r.URL.Path
+		gotVersion = api.APIVersionFromContext(r.Context())
+	})
+
+	cases := []struct {
+		path    string
+		route   string
+		version api.APIVersion
+	}{
+		{path: "/api/v1/channels/abc", route: "/api/channels/abc", version: api.APIVersion1},
+		{path: "/api/channels/abc", route: "/api/channels/abc", version: api.APIVersionLegacy},
+		{path: "/healthz", route: "/healthz", version: api.APIVersionLegacy},
+	}
+	for _, tc := range cases {
+		apiVersionMiddleware(next).ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, tc.path, nil))
+		if gotPath != tc.route || gotVersion != tc.version {
+			t.Errorf("%s: routed to %q as %q, want %q as %q", tc.path, gotPath, gotVersion, tc.route, tc.version)
+		}
+	}
+}
+
+func TestVersionedAndLegacyPrefixesShareHandlers(t *testing.T) {
+	handler, store := newTestHandler(t)
+	admin, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Admin", Email: "admin@example.com", Roles: []string{"admin"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	token, _, err := handler.Sessions.Create(admin.ID)
+	if err != nil {
+		t.Fatalf("create session: %v", err)
+	}
+	var auditBuf bytes.Buffer
+	recorder := metrics.New()
+	srv, err := New(handler, Config{Metrics: recorder, AuditLogger: slog.New(slog.NewJSONHandler(&auditBuf, nil))})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	serve := func(method, path string) *httptest.ResponseRecorder {
+		t.Helper()
+		req := httptest.NewRequest(method, path, nil)
+		req.AddCookie(&http.Cookie{Name: "bitriver_session", Value: token})
+		rec := httptest.NewRecorder()
+		srv.httpServer.Handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	legacy := serve(http.MethodGet, "/api/users")
+	if legacy.Code != http.StatusOK {
+		t.Fatalf("legacy listing: expected 200, got %d: %s", legacy.Code, legacy.Body.String())
+	}
+	var bare []map[string]any
+	if err := json.Unmarshal(legacy.Body.Bytes(), &bare); err != nil || len(bare) != 1 {
+		t.Fatalf("expected a bare array under /api/: %v %s", err, legacy.Body.String())
+	}
+	if legacy.Header().Get("Deprecation") != "true" {
+		t.Fatalf("expected the legacy listing to be marked deprecated")
+	}
+
+	versioned := serve(http.MethodGet, "/api/v1/users")
+	if versioned.Code != http.StatusOK {
+		t.Fatalf("v1 listing: expected 200, got %d: %s", versioned.Code, versioned.Body.String())
+	}
+	var page struct {
+		Items []map[string]any `json:"items"`
+		Total int              `json:"total"`
+	}
+	if err := json.Unmarshal(versioned.Body.Bytes(), &page); err != nil || page.Total != 1 || len(page.Items) != 1 {
+		t.Fatalf("expected the page envelope under /api/v1/: %v %s", err, versioned.Body.String())
+	}
+	if versioned.Header().Get("Deprecation") != "" {
+		t.Fatalf("expected no Deprecation header under /api/v1/")
+	}
+
+	// Public routes keep their optional authentication under the prefix.
+	anonymous := httptest.NewRecorder()
+	srv.httpServer.Handler.ServeHTTP(anonymous, httptest.NewRequest(http.MethodGet, "/api/v1/directory", nil))
+	if anonymous.Code != http.StatusOK {
+		t.Fatalf("anonymous v1 directory: expected 200, got %d: %s", anonymous.Code, anonymous.Body.String())
+	}
+
+	if rec := serve(http.MethodPost, "/api/v1/users"); rec.Code != http.StatusBadRequest {
+		t.Fatalf("v1 create without body: expected 400, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if !strings.Contains(auditBuf.String(), `"path":"/api/v1/users"`) {
+		t.Fatalf("expected the audit entry to carry the versioned path, got %s", auditBuf.String())
+	}
+
+	var metricsBuf bytes.Buffer
+	recorder.Write(&metricsBuf)
+	body := metricsBuf.String()
+	for _, want := range []string{
+		`bitriver_http_requests_total{method="GET",path="/api/users",status="200"} 1`,
+		`bitriver_http_requests_total{method="GET",path="/api/v1/users",status="200"} 1`,
+	} {
+		if !strings.Contains(body, want) {
+			t.Errorf("expected metrics to contain %q, got %s", want, body)
+		}
+	}
+}
@@ -0,0 +1,115 @@
+package server
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"bitriver-live/internal/cache"
+	"bitriver-live/internal/observability/metrics"
+)
+
+// ReadCacheConfig controls the read cache placed in front of the directory and
+// public channel endpoints. The cache is enabled by default with
+// cache.DefaultTTL and shares the rate-limit Redis when one is configured so
+// every replica observes the same invalidations.
+type ReadCacheConfig struct {
+	Disabled bool
+	TTL      time.Duration
+}
+
+const readCacheRedisPrefix = "bitriver:cache:"
+
+// redisCacheBackend stores read cache entries and generations in Redis.
+type redisCacheBackend struct {
+	store *redisStore
+}
+
+var _ cache.Backend = redisCacheBackend{}
+
+func (b redisCacheBackend) Get(ctx context.Context, key string) ([]byte, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, b.store.timeout)
+	defer cancel()
+	reply, err := b.store.client.Do(ctx, "GET", readCacheRedisPrefix+key)
+	if err != nil {
+		return nil, false, err
+	}
+	switch val := reply.(type) {
+	case nil:
+		return nil, false, nil
+	case string:
+		return []byte(val), true, nil
+	case []byte:
+		return val, true, nil
+	default:
+		return nil, false, fmt.Errorf("unexpected redis reply type %T", reply)
+	}
+}
+
+func (b redisCacheBackend) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	ctx, cancel := context.WithTimeout(ctx, b.store.timeout)
+	defer cancel()
+	millis := ttl.Milliseconds()
+	if millis < 1 {
+		millis = 1
+	}
+	_, err := b.store.client.Do(ctx, "SET", readCacheRedisPrefix+key, string(value), "PX", millis)
+	return err
+}
+
+func (b redisCacheBackend) Generations(ctx context.Context, names ...string) ([]int64, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+	ctx, cancel := context.WithTimeout(ctx, b.store.timeout)
+	defer cancel()
+	args := make([]interface{}, 0, len(names)+1)
+	args = append(args, "MGET")
+	for _, name := range names {
+		args = append(args, readCacheRedisPrefix+name)
+	}
+	reply, err := b.store.client.Do(ctx, args...)
+	if err != nil {
+		return nil, err
+	}
+	values, ok := reply.([]interface{})
+	if !ok || len(values) != len(names) {
+		return nil, fmt.Errorf("unexpected redis reply type %T", reply)
+	}
+	generations := make([]int64, len(names))
+	for i, value := range values {
+		if value == nil {
+			continue
+		}
+		generation, err := toInt(value)
+		if err != nil {
+			return nil, err
+		}
+		generations[i] = generation
+	}
+	return generations, nil
+}
+
+func (b redisCacheBackend) Bump(ctx context.Context, name string) error {
+	ctx, cancel := context.WithTimeout(ctx, b.store.timeout)
+	defer cancel()
+	_, err := b.store.client.Do(ctx, "INCR", readCacheRedisPrefix+name)
+	return err
+}
+
+// newReadCache builds the handler's read cache, or returns nil when it is
+// disabled.
+func newReadCache(cfg ReadCacheConfig, rateLimit RateLimitConfig, recorder *metrics.Recorder) (*cache.Cache, error) {
+	if cfg.Disabled {
+		return nil, nil
+	}
+	var backend cache.Backend
+	if rateLimit.redisConfigured() {
+		store, err := newRedisStore(rateLimit.redisStoreConfig())
+		if err != nil {
+			return nil, err
+		}
+		backend = redisCacheBackend{store: store}
+	}
+	return cache.New(cache.Config{Backend: backend, TTL: cfg.TTL, Metrics: recorder}), nil
+}
@@ -52,6 +52,12 @@ func normalizeOrigin(origin string) (string, error) {
 	return fmt.Sprintf("%s://%s", strings.ToLower(parsed.Scheme), strings.ToLower(parsed.Host)), nil
 }
 
+// isPublicCORSPath reports whether path serves anonymous, read-only content
+// that any site may fetch: the oEmbed endpoint and the embed player.
+func isPublicCORSPath(path string) bool {
+	return path == "/api/oembed" || isEmbedPath(path)
+}
+
 func corsMiddleware(policy corsPolicy, logger *slog.Logger, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		origin := strings.TrimSpace(r.Header.Get("Origin"))
@@ -60,6 +66,20 @@ func corsMiddleware(policy corsPolicy, logger *slog.Logger, next http.Handler) h
 			return
 		}
 
+		if isPublicCORSPath(r.URL.Path) {
+			// Credentials are never allowed here, so a wildcard cannot
+			// expose a signed-in viewer's data.
+			w.Header().Set("Access-Control-Allow-Origin", "*")
+			if r.Method == http.MethodOptions {
+				w.Header().Set("Access-Control-Allow-Methods", "GET, HEAD, OPTIONS")
+				w.Header().Set("Access-Control-Allow-Headers", "Content-Type")
+				w.WriteHeader(http.StatusNoContent)
+				return
+			}
+			next.ServeHTTP(w, r)
+			return
+		}
+
 		reqOrigin := originForRequest(r)
 		if !policy.allows(origin, reqOrigin) {
 			if logger != nil {
@@ -72,7 +92,7 @@ func corsMiddleware(policy corsPolicy, logger *slog.Logger, next http.Handler) h
 		w.Header().Set("Access-Control-Allow-Origin", origin)
 		w.Header().Set("Access-Control-Allow-Credentials", "true")
 		w.Header().Set("Vary", "Origin")
-		w.Header().Set("Access-Control-Expose-Headers", "Content-Disposition")
+		w.Header().Set("Access-Control-Expose-Headers", "Content-Disposition, X-RateLimit-Limit, X-RateLimit-Remaining, X-RateLimit-Reset")
 
 		if r.Method == http.MethodOptions {
 			requestedMethod := r.Header.Get("Access-Control-Request-Method")
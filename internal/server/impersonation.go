@@ -0,0 +1,86 @@
+package server
+
+import (
+	"context"
+	"net/http"
+	"strings"
+
+	"bitriver-live/internal/api"
+)
+
+// auditIdentity carries the identities authMiddleware resolves back out to
+// auditMiddleware, which wraps it and so never sees the request context the
+// user is attached to.
+type auditIdentity struct {
+	userID         string
+	impersonatorID string
+}
+
+type auditIdentityKey struct{}
+
+func withAuditIdentity(ctx context.Context) (context.Context, *auditIdentity) {
+	identity := &auditIdentity{}
+	return context.WithValue(ctx, auditIdentityKey{}, identity), identity
+}
+
+// recordAuditIdentity notes who made the request for the audit log. Under
+// impersonation userID is the impersonated account and impersonatorID the
+// administrator acting as them.
+func recordAuditIdentity(ctx context.Context, userID, impersonatorID string) {
+	if identity, ok := ctx.Value(auditIdentityKey{}).(*auditIdentity); ok {
+		identity.userID = userID
+		identity.impersonatorID = impersonatorID
+	}
+}
+
+// impersonationGuardMiddleware refuses requests made under impersonation that
+// would change the impersonated user's credentials or payout details, or
+// destroy their data. It runs inside authMiddleware, which marks impersonated
+// requests on the context.
+func impersonationGuardMiddleware(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, impersonated := api.ImpersonatorFromContext(r.Context()); impersonated && blockedDuringImpersonation(r) {
+			writeMiddlewareError(w, http.StatusForbidden, "not allowed while impersonating a user")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
+// blockedDuringImpersonation reports whether the request targets an endpoint
+// an administrator may not use while acting as another user.
+func blockedDuringImpersonation(r *http.Request) bool {
+	switch r.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return false
+	}
+	path := strings.Trim(r.URL.Path, "/")
+	parts := strings.Split(path, "/")
+	if len(parts) < 3 || parts[0] != "api" {
+		return false
+	}
+	switch parts[1] {
+	case "users":
+		// Password changes, access tokens, and the account record itself,
+		// which carries the email address.
+		if len(parts) == 3 {
+			return true
+		}
+		return parts[2] == "me" && (parts[3] == "password" || parts[3] == "tokens")
+	case "profiles":
+		// Profiles carry the donation addresses tips are paid out to.
+		return len(parts) == 3 && r.Method == http.MethodPut
+	case "channels":
+		if len(parts) == 3 {
+			return r.Method == http.MethodDelete
+		}
+		// Stream keys and restream destinations are channel credentials.
+		if parts[3] == "restreams" {
+			return true
+		}
+		return len(parts) == 5 && parts[3] == "stream" && parts[4] == "rotate"
+	case "admin":
+		return true
+	}
+	return false
+}
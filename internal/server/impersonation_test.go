@@ -0,0 +1,227 @@
+package server
+
+import (
+	"bytes"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/api"
+	"bitriver-live/internal/authz"
+	"bitriver-live/internal/models"
+	"bitriver-live/internal/storage"
+)
+
+type impersonationFixture struct {
+	handler *api.Handler
+	chain   http.Handler
+	audit   *bytes.Buffer
+	admin   models.User
+	viewer  models.User
+}
+
+func newImpersonationFixture(t *testing.T) impersonationFixture {
+	t.Helper()
+	handler, store := newTestHandler(t)
+	admin, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Admin", Email: "admin@example.com", Roles: []string{authz.RoleAdmin}})
+	if err != nil {
+		t.Fatalf("CreateUser admin: %v", err)
+	}
+	viewer, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com", Password: "supersecret"})
+	if err != nil {
+		t.Fatalf("CreateUser viewer: %v", err)
+	}
+	resolver, err := newClientIPResolver(RateLimitConfig{})
+	if err != nil {
+		t.Fatalf("newClientIPResolver error: %v", err)
+	}
+
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/auth/session", handler.Session)
+	mux.HandleFunc("/api/users/", handler.UserByID)
+	mux.HandleFunc("/api/admin/users/", handler.AdminUserByID)
+	audit := &bytes.Buffer{}
+	auditLogger := slog.New(slog.NewTextHandler(audit, nil))
+	logger := slog.New(slog.NewTextHandler(io.Discard, nil))
+
+	chain := http.Handler(mux)
+	chain = requestIDMiddleware(logger, chain)
+	chain = impersonationGuardMiddleware(chain)
+	chain = authMiddleware(handler, chain)
+	chain = auditMiddleware(auditLogger, resolver, chain)
+	return impersonationFixture{handler: handler, chain: chain, audit: audit, admin: admin, viewer: viewer}
+}
+
+func (f impersonationFixture) serve(method, path, body, token string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, strings.NewReader(body))
+	if body != "" {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	req.AddCookie(&http.Cookie{Name: "bitriver_impersonation", Value: token})
+	rec := httptest.NewRecorder()
+	f.chain.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestImpersonationGuardBlocksCredentialChanges(t *testing.T) {
+	f := newImpersonationFixture(t)
+	token, _, err := f.handler.Sessions.CreateImpersonation(f.admin.ID, f.viewer.ID, time.Minute)
+	if err != nil {
+		t.Fatalf("CreateImpersonation: %v", err)
+	}
+
+	blocked := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{method: http.MethodPost, path: "/api/users/me/password", body: `{"currentPassword":"supersecret","newPassword":"evenmoresecret"}`},
+		{method: http.MethodPost, path: "/api/users/me/tokens", body: `{"name":"cli","scopes":["read"]}`},
+		{method: http.MethodPatch, path: "/api/users/" + f.viewer.ID, body: `{"email":"new@example.com"}`},
+		{method: http.MethodPut, path: "/api/profiles/" + f.viewer.ID, body: `{"donationAddresses":[{"currency":"btc","address":"bc1qattacker"}]}`},
+		{method: http.MethodPost, path: "/api/channels/chan-1/stream/rotate"},
+	}
+	for _, tc := range blocked {
+		rec := f.serve(tc.method, tc.path, tc.body, token)
+		if rec.Code != http.StatusForbidden {
+			t.Fatalf("expected %s %s to be blocked, got %d: %s", tc.method, tc.path, rec.Code, rec.Body.String())
+		}
+	}
+	if user, ok := f.handler.Store.GetUser(f.viewer.ID); !ok || user.Email != "viewer@example.com" {
+		t.Fatalf("expected the viewer's email to be unchanged, got %+v", user)
+	}
+
+	rec := f.serve(http.MethodGet, "/api/users/me", "", token)
+	if rec.Code != http.StatusOK || !strings.Contains(rec.Body.String(), f.viewer.ID) {
+		t.Fatalf("expected reads to be served as the viewer, got %d: %s", rec.Code, rec.Body.String())
+	}
+}
+
+func TestImpersonationAuditRecordsBothIdentities(t *testing.T) {
+	f := newImpersonationFixture(t)
+	token, _, err := f.handler.Sessions.CreateImpersonation(f.admin.ID, f.viewer.ID, time.Minute)
+	if err != nil {
+		t.Fatalf("CreateImpersonation: %v", err)
+	}
+
+	f.serve(http.MethodPost, "/api/users/me/password", `{}`, token)
+	logged := f.audit.String()
+	if !strings.Contains(logged, "user_id="+f.viewer.ID) || !strings.Contains(logged, "impersonator_id="+f.admin.ID) {
+		t.Fatalf("expected the audit entry to name both identities, got %q", logged)
+	}
+
+	f.audit.Reset()
+	adminToken, _, err := f.handler.Sessions.Create(f.admin.ID)
+	if err != nil {
+		t.Fatalf("Create session: %v", err)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/api/admin/users/"+f.viewer.ID+"/impersonate", nil)
+	req.AddCookie(&http.Cookie{Name: "bitriver_session", Value: adminToken})
+	rec := httptest.NewRecorder()
+	f.chain.ServeHTTP(rec, req)
+	if rec.Code != http.StatusCreated {
+		t.Fatalf("expected impersonation to start, got %d: %s", rec.Code, rec.Body.String())
+	}
+	logged = f.audit.String()
+	if !strings.Contains(logged, "user_id="+f.admin.ID) || strings.Contains(logged, "impersonator_id") {
+		t.Fatalf("expected the admin's own request to be attributed to them alone, got %q", logged)
+	}
+}
+
+func TestImpersonationSessionExpires(t *testing.T) {
+	f := newImpersonationFixture(t)
+	token, _, err := f.handler.Sessions.CreateImpersonation(f.admin.ID, f.viewer.ID, 50*time.Millisecond)
+	if err != nil {
+		t.Fatalf("CreateImpersonation: %v", err)
+	}
+	if rec := f.serve(http.MethodGet, "/api/users/me", "", token); rec.Code != http.StatusOK {
+		t.Fatalf("expected the impersonation to be valid, got %d", rec.Code)
+	}
+
+	time.Sleep(80 * time.Millisecond)
+	rec := f.serve(http.MethodGet, "/api/users/me", "", token)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected an expired impersonation to be rejected, got %d", rec.Code)
+	}
+	var cleared bool
+	for _, cookie := range rec.Result().Cookies() {
+		if cookie.Name == "bitriver_session" {
+			t.Fatal("expected the admin's session cookie to be left alone")
+		}
+		if cookie.Name == "bitriver_impersonation" && cookie.MaxAge == -1 {
+			cleared = true
+		}
+	}
+	if !cleared {
+		t.Fatal("expected the expired impersonation cookie to be cleared")
+	}
+}
+
+func TestImpersonationEndsWhenAdminLosesAccess(t *testing.T) {
+	demote := func(t *testing.T, store *storage.Storage, admin models.User) {
+		roles := []string{}
+		if _, err := store.UpdateUser(admin.ID, storage.UserUpdate{Roles: &roles}); err != nil {
+			t.Fatalf("UpdateUser roles: %v", err)
+		}
+	}
+	deactivate := func(t *testing.T, store *storage.Storage, admin models.User) {
+		deactivated := true
+		if _, err := store.UpdateUser(admin.ID, storage.UserUpdate{Deactivated: &deactivated}); err != nil {
+			t.Fatalf("UpdateUser deactivated: %v", err)
+		}
+	}
+	remove := func(t *testing.T, store *storage.Storage, admin models.User) {
+		if err := store.DeleteUser(admin.ID); err != nil {
+			t.Fatalf("DeleteUser: %v", err)
+		}
+	}
+	cases := []struct {
+		name   string
+		revoke func(*testing.T, *storage.Storage, models.User)
+	}{
+		{name: "admin role removed", revoke: demote},
+		{name: "admin deactivated", revoke: deactivate},
+		{name: "admin deleted", revoke: remove},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			f := newImpersonationFixture(t)
+			token, _, err := f.handler.Sessions.CreateImpersonation(f.admin.ID, f.viewer.ID, time.Minute)
+			if err != nil {
+				t.Fatalf("CreateImpersonation: %v", err)
+			}
+			if rec := f.serve(http.MethodGet, "/api/users/me", "", token); rec.Code != http.StatusOK {
+				t.Fatalf("expected the impersonation to be valid, got %d", rec.Code)
+			}
+			store, ok := f.handler.Store.(*storage.Storage)
+			if !ok {
+				t.Fatalf("expected a JSON store, got %T", f.handler.Store)
+			}
+			tc.revoke(t, store, f.admin)
+
+			rec := f.serve(http.MethodPatch, "/api/users/"+f.viewer.ID, `{"email":"new@example.com"}`, token)
+			if rec.Code != http.StatusUnauthorized {
+				t.Fatalf("expected the impersonation to be rejected, got %d: %s", rec.Code, rec.Body.String())
+			}
+			var cleared bool
+			for _, cookie := range rec.Result().Cookies() {
+				if cookie.Name == "bitriver_impersonation" && cookie.MaxAge == -1 {
+					cleared = true
+				}
+			}
+			if !cleared {
+				t.Fatal("expected the impersonation cookie to be cleared")
+			}
+			if user, ok := store.GetUser(f.viewer.ID); !ok || user.Email != "viewer@example.com" {
+				t.Fatalf("expected the viewer's email to be unchanged, got %+v", user)
+			}
+			if rec := f.serve(http.MethodGet, "/api/users/me", "", token); rec.Code != http.StatusUnauthorized {
+				t.Fatalf("expected the revoked impersonation to stay rejected, got %d", rec.Code)
+			}
+		})
+	}
+}
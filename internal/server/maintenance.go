@@ -0,0 +1,437 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"bitriver-live/internal/api"
+	"bitriver-live/internal/authz"
+)
+
+// MaintenanceConfig seeds read-only maintenance mode when the server starts.
+// Seeding only applies when no maintenance state is stored, so a restarting
+// replica never overrides a state changed at runtime through
+// POST /api/admin/maintenance.
+type MaintenanceConfig struct {
+	Enabled bool
+	Reason  string
+}
+
+// maintenanceErrorCode is returned in the error body of writes refused while
+// maintenance mode is active so clients can distinguish it from outages.
+const maintenanceErrorCode = "maintenance_mode"
+
+const maintenanceRedisKey = "bitriver:maintenance"
+
+const maxMaintenanceReasonLength = 280
+
+type maintenanceState struct {
+	Enabled   bool       `json:"enabled"`
+	Reason    string     `json:"reason,omitempty"`
+	StartedAt time.Time  `json:"startedAt"`
+	ExpiresAt *time.Time `json:"expiresAt,omitempty"`
+	UpdatedBy string     `json:"updatedBy,omitempty"`
+}
+
+func (s maintenanceState) expired(now time.Time) bool {
+	return s.ExpiresAt != nil && !s.ExpiresAt.After(now)
+}
+
+// maintenanceStore persists the maintenance flag. The Redis implementation
+// shares it across replicas; the memory implementation is per process.
+type maintenanceStore interface {
+	LoadMaintenance(ctx context.Context) (maintenanceState, bool, error)
+	SaveMaintenance(ctx context.Context, state maintenanceState) error
+	// SeedMaintenance saves state only when none is stored and reports
+	// whether it did.
+	SeedMaintenance(ctx context.Context, state maintenanceState) (bool, error)
+	ClearMaintenance(ctx context.Context) error
+}
+
+type memoryMaintenanceStore struct {
+	mu    sync.RWMutex
+	state *maintenanceState
+}
+
+func (s *memoryMaintenanceStore) LoadMaintenance(context.Context) (maintenanceState, bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if s.state == nil {
+		return maintenanceState{}, false, nil
+	}
+	return *s.state, true, nil
+}
+
+func (s *memoryMaintenanceStore) SaveMaintenance(_ context.Context, state maintenanceState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = &state
+	return nil
+}
+
+func (s *memoryMaintenanceStore) SeedMaintenance(_ context.Context, state maintenanceState) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.state != nil {
+		return false, nil
+	}
+	s.state = &state
+	return true, nil
+}
+
+func (s *memoryMaintenanceStore) ClearMaintenance(context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.state = nil
+	return nil
+}
+
+func (s *redisStore) LoadMaintenance(ctx context.Context) (maintenanceState, bool, error) {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	reply, err := s.client.Do(ctx, "GET", maintenanceRedisKey)
+	if err != nil {
+		return maintenanceState{}, false, err
+	}
+	var payload []byte
+	switch val := reply.(type) {
+	case nil:
+		return maintenanceState{}, false, nil
+	case string:
+		payload = []byte(val)
+	case []byte:
+		payload = val
+	default:
+		return maintenanceState{}, false, fmt.Errorf("unexpected redis reply type %T", reply)
+	}
+	var state maintenanceState
+	if err := json.Unmarshal(payload, &state); err != nil {
+		return maintenanceState{}, false, fmt.Errorf("decode maintenance state: %w", err)
+	}
+	return state, true, nil
+}
+
+func (s *redisStore) SaveMaintenance(ctx context.Context, state maintenanceState) error {
+	payload, err := json.Marshal(state)
+	if err != nil {
+		return fmt.Errorf("encode maintenance state: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	args := []interface{}{"SET", maintenanceRedisKey, string(payload)}
+	if state.ExpiresAt != nil {
+		ttl := time.Until(*state.ExpiresAt).Milliseconds()
+		if ttl < 1 {
+			ttl = 1
+		}
+		args = append(args, "PX", ttl)
+	}
+	_, err = s.client.Do(ctx, args...)
+	return err
+}
+
+func (s *redisStore) SeedMaintenance(ctx context.Context, state maintenanceState) (bool, error) {
+	payload, err := json.Marshal(state)
+	if err != nil {
+		return false, fmt.Errorf("encode maintenance state: %w", err)
+	}
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	// NX leaves a state another replica or an administrator stored alone.
+	reply, err := s.client.Do(ctx, "SET", maintenanceRedisKey, string(payload), "NX")
+	if err != nil {
+		return false, err
+	}
+	return reply != nil, nil
+}
+
+func (s *redisStore) ClearMaintenance(ctx context.Context) error {
+	ctx, cancel := context.WithTimeout(ctx, s.timeout)
+	defer cancel()
+	_, err := s.client.Do(ctx, "DEL", maintenanceRedisKey)
+	return err
+}
+
+type maintenanceController struct {
+	store  maintenanceStore
+	logger *slog.Logger
+	now    func() time.Time
+}
+
+func newMaintenanceController(store maintenanceStore, logger *slog.Logger) *maintenanceController {
+	if store == nil {
+		store = &memoryMaintenanceStore{}
+	}
+	return &maintenanceController{store: store, logger: logger, now: time.Now}
+}
+
+// Current returns the active maintenance state, clearing it once the optional
+// expiry has passed.
+func (m *maintenanceController) Current(ctx context.Context) (maintenanceState, error) {
+	state, ok, err := m.store.LoadMaintenance(ctx)
+	if err != nil || !ok {
+		return maintenanceState{}, err
+	}
+	if state.expired(m.now().UTC()) {
+		if err := m.store.ClearMaintenance(ctx); err != nil {
+			return maintenanceState{}, err
+		}
+		return maintenanceState{}, nil
+	}
+	return state, nil
+}
+
+func (m *maintenanceController) Enable(ctx context.Context, reason string, expiresAt *time.Time, actor string) (maintenanceState, error) {
+	now := m.now().UTC()
+	if expiresAt != nil && !expiresAt.After(now) {
+		return maintenanceState{}, errors.New("expiresAt must be in the future")
+	}
+	state := maintenanceState{
+		Enabled:   true,
+		Reason:    strings.TrimSpace(reason),
+		StartedAt: now,
+		UpdatedBy: actor,
+	}
+	if expiresAt != nil {
+		expires := expiresAt.UTC()
+		state.ExpiresAt = &expires
+	}
+	if err := m.store.SaveMaintenance(ctx, state); err != nil {
+		return maintenanceState{}, err
+	}
+	return state, nil
+}
+
+// Seed enables maintenance from startup configuration unless a state is
+// already stored, which every replica starting with the same configuration
+// would otherwise overwrite. It reports whether maintenance was enabled.
+func (m *maintenanceController) Seed(ctx context.Context, reason, actor string) (bool, error) {
+	if _, err := m.Current(ctx); err != nil {
+		return false, err
+	}
+	state := maintenanceState{
+		Enabled:   true,
+		Reason:    strings.TrimSpace(reason),
+		StartedAt: m.now().UTC(),
+		UpdatedBy: actor,
+	}
+	return m.store.SeedMaintenance(ctx, state)
+}
+
+func (m *maintenanceController) Disable(ctx context.Context) error {
+	return m.store.ClearMaintenance(ctx)
+}
+
+// chatWriteGuard refuses new chat messages sent over the WebSocket gateway,
+// which bypasses the HTTP middleware once the connection is upgraded.
+func (m *maintenanceController) chatWriteGuard(ctx context.Context) error {
+	state, err := m.Current(ctx)
+	if err != nil {
+		m.logError(err)
+		return nil
+	}
+	if state.Enabled {
+		return errors.New(maintenanceMessage(state))
+	}
+	return nil
+}
+
+func (m *maintenanceController) logError(err error) {
+	if m.logger != nil {
+		m.logger.Warn("maintenance state unavailable; allowing writes", "error", err)
+	}
+}
+
+func maintenanceMessage(state maintenanceState) string {
+	if state.Reason == "" {
+		return "the platform is in read-only maintenance mode"
+	}
+	return "the platform is in read-only maintenance mode: " + state.Reason
+}
+
+// maintenanceExempt reports whether a write should be allowed while the
+// platform is read-only. Sign-in, sign-out, and session checks keep working so
+// admins can lift maintenance and users can end their sessions, and ingest
+// callbacks keep viewer counts accurate.
+func maintenanceExempt(path string) bool {
+	switch path {
+	case "/api/auth/login", "/api/auth/logout", "/api/auth/session", "/api/admin/maintenance", "/api/ingest/srs-hook", "/api/internal/ingest/events":
+		return true
+	}
+	return strings.HasPrefix(path, "/api/auth/oauth/")
+}
+
+func maintenanceMiddleware(m *maintenanceController, next http.Handler) http.Handler {
+	if m == nil {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, "/api/") || maintenanceExempt(r.URL.Path) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		switch r.Method {
+		case http.MethodGet, http.MethodHead, http.MethodOptions:
+			next.ServeHTTP(w, r)
+			return
+		}
+		state, err := m.Current(r.Context())
+		if err != nil {
+			m.logError(err)
+			next.ServeHTTP(w, r)
+			return
+		}
+		if !state.Enabled {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if state.ExpiresAt != nil {
+			retry := time.Until(*state.ExpiresAt).Seconds()
+			if retry < 1 {
+				retry = 1
+			}
+			w.Header().Set("Retry-After", strconv.Itoa(int(retry)))
+		}
+		api.WriteError(w, http.StatusServiceUnavailable, api.RequestError{
+			Status:  http.StatusServiceUnavailable,
+			CodeVal: maintenanceErrorCode,
+			Message: maintenanceMessage(state),
+		})
+	})
+}
+
+type maintenanceStatusResponse struct {
+	Enabled   bool    `json:"enabled"`
+	Reason    string  `json:"reason,omitempty"`
+	StartedAt *string `json:"startedAt,omitempty"`
+	ExpiresAt *string `json:"expiresAt,omitempty"`
+	UpdatedBy string  `json:"updatedBy,omitempty"`
+}
+
+func newMaintenanceStatusResponse(state maintenanceState, includeActor bool) maintenanceStatusResponse {
+	if !state.Enabled {
+		return maintenanceStatusResponse{}
+	}
+	started := state.StartedAt.Format(time.RFC3339Nano)
+	response := maintenanceStatusResponse{
+		Enabled:   true,
+		Reason:    state.Reason,
+		StartedAt: &started,
+	}
+	if state.ExpiresAt != nil {
+		expires := state.ExpiresAt.Format(time.RFC3339Nano)
+		response.ExpiresAt = &expires
+	}
+	if includeActor {
+		response.UpdatedBy = state.UpdatedBy
+	}
+	return response
+}
+
+// handleStatus serves GET /api/maintenance so the viewer and control centre
+// can render a banner without authenticating.
+func (m *maintenanceController) handleStatus(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		api.WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodHead)
+		return
+	}
+	state, err := m.Current(r.Context())
+	if err != nil {
+		api.WriteError(w, http.StatusServiceUnavailable, fmt.Errorf("maintenance state unavailable"))
+		return
+	}
+	api.WriteJSON(w, http.StatusOK, newMaintenanceStatusResponse(state, false))
+}
+
+type maintenanceToggleRequest struct {
+	Enabled   bool    `json:"enabled"`
+	Reason    string  `json:"reason"`
+	ExpiresAt *string `json:"expiresAt"`
+}
+
+// validate enforces a reason when enabling maintenance so the banner always
+// explains why writes are refused.
+func (r maintenanceToggleRequest) validate() error {
+	if r.Enabled && strings.TrimSpace(r.Reason) == "" {
+		return api.ValidationError("reason is required when enabling maintenance")
+	}
+	if len(r.Reason) > maxMaintenanceReasonLength {
+		return api.ValidationError(fmt.Sprintf("reason must be %d characters or fewer", maxMaintenanceReasonLength))
+	}
+	return nil
+}
+
+// handleAdmin serves /api/admin/maintenance for administrators.
+func (m *maintenanceController) handleAdmin(w http.ResponseWriter, r *http.Request) {
+	user, ok := api.UserFromContext(r.Context())
+	if !ok {
+		api.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+		return
+	}
+	if !authz.Has(user, authz.PlatformManage) {
+		api.WriteError(w, http.StatusForbidden, fmt.Errorf("forbidden"))
+		return
+	}
+
+	switch r.Method {
+	case http.MethodGet:
+		state, err := m.Current(r.Context())
+		if err != nil {
+			api.WriteError(w, http.StatusServiceUnavailable, fmt.Errorf("maintenance state unavailable"))
+			return
+		}
+		api.WriteJSON(w, http.StatusOK, newMaintenanceStatusResponse(state, true))
+	case http.MethodPost:
+		var req maintenanceToggleRequest
+		if !api.DecodeAndValidate(w, r, &req) {
+			return
+		}
+		if err := req.validate(); err != nil {
+			api.WriteRequestError(w, err)
+			return
+		}
+		if !req.Enabled {
+			if err := m.Disable(r.Context()); err != nil {
+				api.WriteError(w, http.StatusServiceUnavailable, fmt.Errorf("update maintenance state: %w", err))
+				return
+			}
+			if m.logger != nil {
+				m.logger.Info("maintenance mode disabled", "user_id", user.ID)
+			}
+			api.WriteJSON(w, http.StatusOK, maintenanceStatusResponse{})
+			return
+		}
+		var expiresAt *time.Time
+		if req.ExpiresAt != nil && strings.TrimSpace(*req.ExpiresAt) != "" {
+			parsed, err := time.Parse(time.RFC3339, strings.TrimSpace(*req.ExpiresAt))
+			if err != nil {
+				api.WriteRequestError(w, api.ValidationError("expiresAt must be an RFC3339 timestamp"))
+				return
+			}
+			if !parsed.After(m.now()) {
+				api.WriteRequestError(w, api.ValidationError("expiresAt must be in the future"))
+				return
+			}
+			expiresAt = &parsed
+		}
+		state, err := m.Enable(r.Context(), req.Reason, expiresAt, user.ID)
+		if err != nil {
+			api.WriteError(w, http.StatusServiceUnavailable, fmt.Errorf("update maintenance state: %w", err))
+			return
+		}
+		if m.logger != nil {
+			m.logger.Info("maintenance mode enabled", "user_id", user.ID, "reason", state.Reason)
+		}
+		api.WriteJSON(w, http.StatusOK, newMaintenanceStatusResponse(state, true))
+	default:
+		api.WriteMethodNotAllowed(w, r, http.MethodGet, http.MethodPost)
+	}
+}
@@ -0,0 +1,257 @@
+package server
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/api"
+	"bitriver-live/internal/storage"
+)
+
+func newMaintenanceTestServer(t *testing.T, cfg MaintenanceConfig) (*Server, *api.Handler, *storage.Storage) {
+	t.Helper()
+	handler, store := newTestHandler(t)
+	srv, err := New(handler, Config{Addr: ":0", Maintenance: cfg})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	return srv, handler, store
+}
+
+func TestMaintenanceModeRejectsWritesAndAllowsReads(t *testing.T) {
+	srv, _, _ := newMaintenanceTestServer(t, MaintenanceConfig{Enabled: true, Reason: "database upgrade"})
+	chain := srv.httpServer.Handler
+
+	writeReq := httptest.NewRequest(http.MethodPost, "/api/auth/signup", strings.NewReader(`{"displayName":"Viewer","email":"viewer@example.com","password":"supersecret"}`))
+	writeRec := httptest.NewRecorder()
+	chain.ServeHTTP(writeRec, writeReq)
+	if writeRec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected write to be rejected with 503, got %d: %s", writeRec.Code, writeRec.Body.String())
+	}
+	resp := decodeAPIError(t, writeRec.Body.Bytes())
+	if resp.Error.Code != maintenanceErrorCode {
+		t.Fatalf("expected error code %q, got %q", maintenanceErrorCode, resp.Error.Code)
+	}
+	if !strings.Contains(resp.Error.Message, "database upgrade") {
+		t.Fatalf("expected reason in error message, got %q", resp.Error.Message)
+	}
+
+	readReq := httptest.NewRequest(http.MethodGet, "/api/directory", nil)
+	readRec := httptest.NewRecorder()
+	chain.ServeHTTP(readRec, readReq)
+	if readRec.Code != http.StatusOK {
+		t.Fatalf("expected read to succeed, got %d: %s", readRec.Code, readRec.Body.String())
+	}
+
+	statusReq := httptest.NewRequest(http.MethodGet, "/api/maintenance", nil)
+	statusRec := httptest.NewRecorder()
+	chain.ServeHTTP(statusRec, statusReq)
+	if statusRec.Code != http.StatusOK {
+		t.Fatalf("expected status endpoint to succeed, got %d", statusRec.Code)
+	}
+	var status maintenanceStatusResponse
+	if err := json.Unmarshal(statusRec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("decode status: %v", err)
+	}
+	if !status.Enabled || status.Reason != "database upgrade" {
+		t.Fatalf("unexpected status payload: %+v", status)
+	}
+	if status.UpdatedBy != "" {
+		t.Fatalf("expected public status to omit actor, got %q", status.UpdatedBy)
+	}
+}
+
+func TestMaintenanceModeExemptEndpoints(t *testing.T) {
+	srv, handler, store := newMaintenanceTestServer(t, MaintenanceConfig{Enabled: true, Reason: "migration"})
+	chain := srv.httpServer.Handler
+
+	if _, err := store.CreateUser(storage.CreateUserParams{
+		DisplayName: "Admin",
+		Email:       "admin@example.com",
+		Password:    "supersecret",
+		Roles:       []string{"admin"},
+	}); err != nil {
+		t.Fatalf("CreateUser error: %v", err)
+	}
+	viewer, err := store.CreateUser(storage.CreateUserParams{
+		DisplayName: "Viewer",
+		Email:       "viewer@example.com",
+	})
+	if err != nil {
+		t.Fatalf("CreateUser error: %v", err)
+	}
+
+	loginReq := httptest.NewRequest(http.MethodPost, "/api/auth/login", strings.NewReader(`{"email":"admin@example.com","password":"supersecret"}`))
+	loginRec := httptest.NewRecorder()
+	chain.ServeHTTP(loginRec, loginReq)
+	if loginRec.Code != http.StatusOK {
+		t.Fatalf("expected login to succeed during maintenance, got %d: %s", loginRec.Code, loginRec.Body.String())
+	}
+	adminCookie := findSessionCookie(t, loginRec.Result().Cookies())
+
+	viewerToken, _, err := handler.Sessions.Create(viewer.ID)
+	if err != nil {
+		t.Fatalf("Create session: %v", err)
+	}
+	forbiddenReq := httptest.NewRequest(http.MethodPost, "/api/admin/maintenance", strings.NewReader(`{"enabled":false}`))
+	forbiddenReq.AddCookie(&http.Cookie{Name: "bitriver_session", Value: viewerToken})
+	forbiddenRec := httptest.NewRecorder()
+	chain.ServeHTTP(forbiddenRec, forbiddenReq)
+	if forbiddenRec.Code != http.StatusForbidden {
+		t.Fatalf("expected non-admin toggle to be forbidden, got %d", forbiddenRec.Code)
+	}
+
+	logoutReq := httptest.NewRequest(http.MethodDelete, "/api/auth/session", nil)
+	logoutReq.AddCookie(&http.Cookie{Name: "bitriver_session", Value: viewerToken})
+	logoutRec := httptest.NewRecorder()
+	chain.ServeHTTP(logoutRec, logoutReq)
+	if logoutRec.Code != http.StatusNoContent {
+		t.Fatalf("expected logout to succeed during maintenance, got %d: %s", logoutRec.Code, logoutRec.Body.String())
+	}
+	for _, path := range []string{"/api/auth/login", "/api/auth/logout", "/api/auth/session", "/api/auth/oauth/github/callback"} {
+		if !maintenanceExempt(path) {
+			t.Fatalf("expected %s to stay writable during maintenance", path)
+		}
+	}
+
+	disableReq := httptest.NewRequest(http.MethodPost, "/api/admin/maintenance", strings.NewReader(`{"enabled":false}`))
+	disableReq.AddCookie(adminCookie)
+	disableRec := httptest.NewRecorder()
+	chain.ServeHTTP(disableRec, disableReq)
+	if disableRec.Code != http.StatusOK {
+		t.Fatalf("expected admin toggle to succeed during maintenance, got %d: %s", disableRec.Code, disableRec.Body.String())
+	}
+
+	writeReq := httptest.NewRequest(http.MethodPost, "/api/channels", strings.NewReader(`{"title":"After maintenance"}`))
+	writeReq.AddCookie(adminCookie)
+	writeRec := httptest.NewRecorder()
+	chain.ServeHTTP(writeRec, writeReq)
+	if writeRec.Code == http.StatusServiceUnavailable {
+		t.Fatalf("expected writes to resume after disabling maintenance, got %d: %s", writeRec.Code, writeRec.Body.String())
+	}
+
+	enableReq := httptest.NewRequest(http.MethodPost, "/api/admin/maintenance", strings.NewReader(`{"enabled":true}`))
+	enableReq.AddCookie(adminCookie)
+	enableRec := httptest.NewRecorder()
+	chain.ServeHTTP(enableRec, enableReq)
+	if enableRec.Code != http.StatusBadRequest {
+		t.Fatalf("expected enabling without a reason to fail validation, got %d", enableRec.Code)
+	}
+}
+
+func TestMaintenanceModeAutoExpires(t *testing.T) {
+	now := time.Date(2024, 5, 1, 12, 0, 0, 0, time.UTC)
+	controller := newMaintenanceController(nil, nil)
+	controller.now = func() time.Time { return now }
+
+	expires := now.Add(10 * time.Minute)
+	if _, err := controller.Enable(context.Background(), "short window", &expires, "admin"); err != nil {
+		t.Fatalf("Enable returned error: %v", err)
+	}
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	chain := maintenanceMiddleware(controller, next)
+
+	rec := httptest.NewRecorder()
+	chain.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/channels", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected write to be rejected before expiry, got %d", rec.Code)
+	}
+	if rec.Header().Get("Retry-After") == "" {
+		t.Fatal("expected Retry-After header when maintenance has an expiry")
+	}
+
+	now = expires.Add(time.Second)
+	rec = httptest.NewRecorder()
+	chain.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/channels", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected write to pass after expiry, got %d", rec.Code)
+	}
+	state, err := controller.Current(context.Background())
+	if err != nil {
+		t.Fatalf("Current returned error: %v", err)
+	}
+	if state.Enabled {
+		t.Fatal("expected maintenance to be cleared after expiry")
+	}
+}
+
+func TestMaintenanceModeSharedAcrossInstances(t *testing.T) {
+	shared := &memoryMaintenanceStore{}
+	first := newMaintenanceController(shared, nil)
+	second := newMaintenanceController(shared, nil)
+
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	})
+	firstChain := maintenanceMiddleware(first, next)
+	secondChain := maintenanceMiddleware(second, next)
+
+	if _, err := first.Enable(context.Background(), "rolling deploy", nil, "admin"); err != nil {
+		t.Fatalf("Enable returned error: %v", err)
+	}
+	rec := httptest.NewRecorder()
+	secondChain.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/channels", nil))
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected second instance to observe maintenance, got %d", rec.Code)
+	}
+
+	if err := second.Disable(context.Background()); err != nil {
+		t.Fatalf("Disable returned error: %v", err)
+	}
+	rec = httptest.NewRecorder()
+	firstChain.ServeHTTP(rec, httptest.NewRequest(http.MethodPost, "/api/channels", nil))
+	if rec.Code != http.StatusNoContent {
+		t.Fatalf("expected first instance to observe maintenance being lifted, got %d", rec.Code)
+	}
+}
+
+func TestMaintenanceSeedKeepsStoredState(t *testing.T) {
+	shared := &memoryMaintenanceStore{}
+	admin := newMaintenanceController(shared, nil)
+	replica := newMaintenanceController(shared, nil)
+	ctx := context.Background()
+
+	expires := time.Now().Add(time.Hour)
+	if _, err := admin.Enable(ctx, "database upgrade", &expires, "admin"); err != nil {
+		t.Fatalf("Enable returned error: %v", err)
+	}
+	seeded, err := replica.Seed(ctx, "from config", "config")
+	if err != nil || seeded {
+		t.Fatalf("expected seeding to leave the stored state alone, got seeded=%v err=%v", seeded, err)
+	}
+	state, err := replica.Current(ctx)
+	if err != nil || state.Reason != "database upgrade" || state.UpdatedBy != "admin" || state.ExpiresAt == nil {
+		t.Fatalf("expected the administrator's state to survive, got %+v (err %v)", state, err)
+	}
+
+	if err := admin.Disable(ctx); err != nil {
+		t.Fatalf("Disable returned error: %v", err)
+	}
+	seeded, err = replica.Seed(ctx, "from config", "config")
+	if err != nil || !seeded {
+		t.Fatalf("expected seeding an empty store to enable maintenance, got seeded=%v err=%v", seeded, err)
+	}
+	if state, err := admin.Current(ctx); err != nil || !state.Enabled || state.Reason != "from config" {
+		t.Fatalf("expected the seeded state to be shared, got %+v (err %v)", state, err)
+	}
+}
+
+func TestMaintenanceModeBlocksChatGatewayWrites(t *testing.T) {
+	controller := newMaintenanceController(nil, nil)
+	if err := controller.chatWriteGuard(context.Background()); err != nil {
+		t.Fatalf("expected chat writes to be allowed, got %v", err)
+	}
+	if _, err := controller.Enable(context.Background(), "chat freeze", nil, "admin"); err != nil {
+		t.Fatalf("Enable returned error: %v", err)
+	}
+	if err := controller.chatWriteGuard(context.Background()); err == nil {
+		t.Fatal("expected chat writes to be refused during maintenance")
+	}
+}
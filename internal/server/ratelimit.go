@@ -3,8 +3,13 @@ package server
 import (
 	"context"
 	"fmt"
+	"math"
+	"net/http"
+	"strconv"
 	"sync"
 	"time"
+
+	"bitriver-live/internal/api"
 )
 
 type RateLimitConfig struct {
@@ -24,13 +29,51 @@ type RateLimitConfig struct {
 	RedisTLS              RedisTLSConfig
 }
 
+func (cfg RateLimitConfig) redisConfigured() bool {
+	return cfg.RedisAddr != "" || len(cfg.RedisAddrs) > 0
+}
+
+func (cfg RateLimitConfig) redisStoreConfig() redisStoreConfig {
+	return redisStoreConfig{
+		Addr:       cfg.RedisAddr,
+		Addrs:      cfg.RedisAddrs,
+		Username:   cfg.RedisUsername,
+		Password:   cfg.RedisPassword,
+		MasterName: cfg.RedisMasterName,
+		Timeout:    cfg.RedisTimeout,
+		PoolSize:   cfg.RedisPoolSize,
+		TLS:        cfg.RedisTLS,
+	}
+}
+
+// Rate limit buckets a request can be counted against. The global bucket is
+// shared by every request the process serves; the login bucket is keyed by
+// client IP and only applies to the authentication endpoints.
+const (
+	rateLimitBucketGlobal = "global"
+	rateLimitBucketLogin  = "login"
+)
+
+// rateLimitInspectPath reports the caller's buckets. It is exempt from
+// limiting so checking the remaining quota never spends it.
+const rateLimitInspectPath = "/api/users/me/rate-limit"
+
+// rateLimitState is one bucket as a request left it. Remaining is never
+// negative and Reset is how long until the bucket is full again. RetryAfter
+// is only set when the request was refused.
+type rateLimitState struct {
+	Bucket     string
+	Limit      int
+	Remaining  int
+	Reset      time.Duration
+	RetryAfter time.Duration
+}
+
 type rateLimiter struct {
-	global       *tokenBucket
-	loginLimit   int
-	loginWindow  time.Duration
-	loginMu      sync.Mutex
-	loginBuckets map[string]*ipLimiter
-	store        tokenStore
+	global      *tokenBucket
+	loginLimit  int
+	loginWindow time.Duration
+	store       tokenStore
 }
 
 type ipLimiter struct {
@@ -38,15 +81,19 @@ type ipLimiter struct {
 	lastSeen time.Time
 }
 
+// tokenStore counts requests per key. Allow takes one request from the key's
+// budget and reports the budget as that request left it, computed in the same
+// step so concurrent callers each see their own remaining count. Inspect
+// reports the budget without taking from it.
 type tokenStore interface {
-	Allow(key string, limit int, window time.Duration) (bool, time.Duration, error)
+	Allow(key string, limit int, window time.Duration) (bool, rateLimitState, error)
+	Inspect(key string, limit int, window time.Duration) (rateLimitState, error)
 }
 
 func newRateLimiter(cfg RateLimitConfig) (*rateLimiter, error) {
 	rl := &rateLimiter{
-		loginLimit:   cfg.LoginLimit,
-		loginWindow:  cfg.LoginWindow,
-		loginBuckets: make(map[string]*ipLimiter),
+		loginLimit:  cfg.LoginLimit,
+		loginWindow: cfg.LoginWindow,
 	}
 	if cfg.GlobalRPS > 0 {
 		burst := cfg.GlobalBurst
@@ -64,74 +111,74 @@ func newRateLimiter(cfg RateLimitConfig) (*rateLimiter, error) {
 	if rl.loginWindow <= 0 {
 		rl.loginWindow = time.Minute
 	}
-	if rl.loginLimit > 0 && (cfg.RedisAddr != "" || len(cfg.RedisAddrs) > 0) {
-		storeCfg := redisStoreConfig{
-			Addr:       cfg.RedisAddr,
-			Addrs:      cfg.RedisAddrs,
-			Username:   cfg.RedisUsername,
-			Password:   cfg.RedisPassword,
-			MasterName: cfg.RedisMasterName,
-			Timeout:    cfg.RedisTimeout,
-			PoolSize:   cfg.RedisPoolSize,
-			TLS:        cfg.RedisTLS,
-		}
-		store, err := newRedisStore(storeCfg)
-		if err != nil {
-			return nil, err
+	if rl.loginLimit > 0 {
+		if cfg.redisConfigured() {
+			store, err := newRedisStore(cfg.redisStoreConfig())
+			if err != nil {
+				return nil, err
+			}
+			rl.store = store
+		} else {
+			rl.store = newMemoryStore()
 		}
-		rl.store = store
 	}
 	return rl, nil
 }
 
-func (r *rateLimiter) AllowRequest() bool {
+// AllowRequest takes a token from the global bucket. The returned state is
+// nil when no global limit is configured.
+func (r *rateLimiter) AllowRequest() (bool, *rateLimitState) {
 	if r == nil || r.global == nil {
-		return true
+		return true, nil
 	}
-	return r.global.Allow()
+	allowed, state := r.global.take()
+	state.Bucket = rateLimitBucketGlobal
+	return allowed, &state
 }
 
-func (r *rateLimiter) AllowLogin(key string) (bool, time.Duration, error) {
-	if r == nil || r.loginLimit <= 0 {
-		return true, 0, nil
+// AllowLogin counts an authentication attempt against key's login bucket.
+// The returned state is nil when login throttling is disabled.
+func (r *rateLimiter) AllowLogin(key string) (bool, *rateLimitState, error) {
+	if r == nil || r.loginLimit <= 0 || r.store == nil {
+		return true, nil, nil
 	}
-	if r.store != nil {
-		allowed, retryAfter, err := r.store.Allow(fmt.Sprintf("bitriver:login:%s", key), r.loginLimit, r.loginWindow)
-		return allowed, retryAfter, err
+	allowed, state, err := r.store.Allow(loginBucketKey(key), r.loginLimit, r.loginWindow)
+	if err != nil {
+		return false, nil, err
 	}
-	if key == "" {
-		key = "unknown"
+	state.Bucket = rateLimitBucketLogin
+	return allowed, &state, nil
+}
+
+// Inspect reports the buckets that apply to a client at key without taking
+// from them: the global bucket and the client's login bucket, when each is
+// configured.
+func (r *rateLimiter) Inspect(key string) ([]rateLimitState, error) {
+	states := make([]rateLimitState, 0, 2)
+	if r == nil {
+		return states, nil
 	}
-	r.loginMu.Lock()
-	bucket, exists := r.loginBuckets[key]
-	if !exists {
-		rate := float64(r.loginLimit) / r.loginWindow.Seconds()
-		if rate <= 0 {
-			rate = 1 / r.loginWindow.Seconds()
-		}
-		bucket = &ipLimiter{bucket: newTokenBucket(rate, r.loginLimit)}
-		r.loginBuckets[key] = bucket
+	if r.global != nil {
+		state := r.global.inspect()
+		state.Bucket = rateLimitBucketGlobal
+		states = append(states, state)
 	}
-	bucket.lastSeen = time.Now()
-	r.cleanupLocked()
-	r.loginMu.Unlock()
-
-	if bucket.bucket.Allow() {
-		return true, 0, nil
+	if r.loginLimit > 0 && r.store != nil {
+		state, err := r.store.Inspect(loginBucketKey(key), r.loginLimit, r.loginWindow)
+		if err != nil {
+			return nil, err
+		}
+		state.Bucket = rateLimitBucketLogin
+		states = append(states, state)
 	}
-	return false, time.Second, nil
+	return states, nil
 }
 
-func (r *rateLimiter) cleanupLocked() {
-	if len(r.loginBuckets) == 0 {
-		return
-	}
-	cutoff := time.Now().Add(-2 * r.loginWindow)
-	for key, bucket := range r.loginBuckets {
-		if bucket.lastSeen.Before(cutoff) {
-			delete(r.loginBuckets, key)
-		}
+func loginBucketKey(key string) string {
+	if key == "" {
+		key = "unknown"
 	}
+	return fmt.Sprintf("bitriver:login:%s", key)
 }
 
 func (r *rateLimiter) Ping(ctx context.Context) error {
@@ -144,6 +191,65 @@ func (r *rateLimiter) Ping(ctx context.Context) error {
 	return nil
 }
 
+// memoryStore is the in-process tokenStore used when Redis is not
+// configured. Each key gets a token bucket refilling limit tokens per window.
+type memoryStore struct {
+	mu      sync.Mutex
+	buckets map[string]*ipLimiter
+}
+
+func newMemoryStore() *memoryStore {
+	return &memoryStore{buckets: make(map[string]*ipLimiter)}
+}
+
+func (s *memoryStore) Allow(key string, limit int, window time.Duration) (bool, rateLimitState, error) {
+	s.mu.Lock()
+	bucket, exists := s.buckets[key]
+	if !exists {
+		bucket = &ipLimiter{bucket: newTokenBucket(memoryStoreRate(limit, window), limit)}
+		s.buckets[key] = bucket
+	}
+	bucket.lastSeen = time.Now()
+	s.cleanupLocked(window)
+	s.mu.Unlock()
+
+	allowed, state := bucket.bucket.take()
+	if !allowed {
+		state.RetryAfter = time.Second
+	}
+	return allowed, state, nil
+}
+
+func (s *memoryStore) Inspect(key string, limit int, window time.Duration) (rateLimitState, error) {
+	s.mu.Lock()
+	bucket, exists := s.buckets[key]
+	s.mu.Unlock()
+	if !exists {
+		return rateLimitState{Limit: limit, Remaining: limit}, nil
+	}
+	return bucket.bucket.inspect(), nil
+}
+
+func memoryStoreRate(limit int, window time.Duration) float64 {
+	rate := float64(limit) / window.Seconds()
+	if rate <= 0 {
+		rate = 1 / window.Seconds()
+	}
+	return rate
+}
+
+func (s *memoryStore) cleanupLocked(window time.Duration) {
+	if len(s.buckets) == 0 {
+		return
+	}
+	cutoff := time.Now().Add(-2 * window)
+	for key, bucket := range s.buckets {
+		if bucket.lastSeen.Before(cutoff) {
+			delete(s.buckets, key)
+		}
+	}
+}
+
 type tokenBucket struct {
 	mu        sync.Mutex
 	rate      float64
@@ -168,19 +274,108 @@ func newTokenBucket(rate float64, burst int) *tokenBucket {
 	}
 }
 
-func (tb *tokenBucket) Allow() bool {
+// take consumes a token when one is available and reports the bucket as this
+// call left it. The refill, the take, and the snapshot happen under one lock.
+func (tb *tokenBucket) take() (bool, rateLimitState) {
 	tb.mu.Lock()
 	defer tb.mu.Unlock()
 	now := time.Now()
-	elapsed := now.Sub(tb.lastCheck).Seconds()
+	tb.tokens = tb.refilled(now)
 	tb.lastCheck = now
-	tb.tokens += elapsed * tb.rate
-	if tb.tokens > tb.capacity {
-		tb.tokens = tb.capacity
-	}
 	if tb.tokens < 1 {
-		return false
+		state := tb.stateAt(tb.tokens)
+		state.RetryAfter = time.Duration((1 - tb.tokens) / tb.rate * float64(time.Second))
+		return false, state
 	}
 	tb.tokens -= 1
-	return true
+	return true, tb.stateAt(tb.tokens)
+}
+
+// inspect reports the bucket without consuming or storing the refill.
+func (tb *tokenBucket) inspect() rateLimitState {
+	tb.mu.Lock()
+	defer tb.mu.Unlock()
+	return tb.stateAt(tb.refilled(time.Now()))
+}
+
+func (tb *tokenBucket) refilled(now time.Time) float64 {
+	tokens := tb.tokens + now.Sub(tb.lastCheck).Seconds()*tb.rate
+	if tokens > tb.capacity {
+		tokens = tb.capacity
+	}
+	return tokens
+}
+
+func (tb *tokenBucket) stateAt(tokens float64) rateLimitState {
+	remaining := int(math.Floor(tokens))
+	if remaining < 0 {
+		remaining = 0
+	}
+	return rateLimitState{
+		Limit:     int(tb.capacity),
+		Remaining: remaining,
+		Reset:     time.Duration((tb.capacity - tokens) / tb.rate * float64(time.Second)),
+	}
+}
+
+// setRateLimitHeaders describes the bucket that applied to a request. Reset
+// is the number of seconds until the bucket is full again.
+func setRateLimitHeaders(w http.ResponseWriter, state *rateLimitState) {
+	if state == nil {
+		return
+	}
+	header := w.Header()
+	header.Set("X-RateLimit-Limit", strconv.Itoa(state.Limit))
+	header.Set("X-RateLimit-Remaining", strconv.Itoa(state.Remaining))
+	header.Set("X-RateLimit-Reset", strconv.FormatInt(ceilSeconds(state.Reset), 10))
+}
+
+func ceilSeconds(d time.Duration) int64 {
+	if d <= 0 {
+		return 0
+	}
+	return int64(math.Ceil(d.Seconds()))
+}
+
+type rateLimitBucketResponse struct {
+	Name         string `json:"name"`
+	Limit        int    `json:"limit"`
+	Remaining    int    `json:"remaining"`
+	ResetSeconds int64  `json:"resetSeconds"`
+}
+
+type rateLimitResponse struct {
+	Buckets []rateLimitBucketResponse `json:"buckets"`
+}
+
+// handleInspect serves GET /api/users/me/rate-limit with the buckets that
+// apply to the caller and their remaining capacity.
+func (r *rateLimiter) handleInspect(resolver *clientIPResolver) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		if req.Method != http.MethodGet {
+			api.WriteMethodNotAllowed(w, req, http.MethodGet)
+			return
+		}
+		if _, ok := api.UserFromContext(req.Context()); !ok {
+			api.WriteError(w, http.StatusUnauthorized, fmt.Errorf("authentication required"))
+			return
+		}
+		ip, _ := resolveClientIP(req, resolver)
+		states, err := r.Inspect(ip)
+		if err != nil {
+			api.WriteError(w, http.StatusServiceUnavailable, fmt.Errorf("rate limit state unavailable"))
+			return
+		}
+		resp := rateLimitResponse{Buckets: make([]rateLimitBucketResponse, 0, len(states))}
+		for _, state := range states {
+			resp.Buckets = append(resp.Buckets, rateLimitBucketResponse{
+				Name:         state.Bucket,
+				Limit:        state.Limit,
+				Remaining:    state.Remaining,
+				ResetSeconds: ceilSeconds(state.Reset),
+			})
+		}
+		w.Header().Set("Cache-Control", "no-store")
+		api.WriteJSON(w, http.StatusOK, resp)
+	}
 }
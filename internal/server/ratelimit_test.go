@@ -0,0 +1,245 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sort"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/api"
+	"bitriver-live/internal/models"
+)
+
+// runTokenStoreSuite checks the behaviour every tokenStore must share so the
+// in-process and Redis-backed login limits report identical budgets.
+func runTokenStoreSuite(t *testing.T, store tokenStore) {
+	t.Helper()
+	const limit = 3
+	window := time.Minute
+
+	state, err := store.Inspect("suite:fresh", limit, window)
+	if err != nil {
+		t.Fatalf("Inspect fresh: %v", err)
+	}
+	if state.Limit != limit || state.Remaining != limit || state.Reset != 0 {
+		t.Fatalf("expected untouched key to report the full budget, got %+v", state)
+	}
+
+	for i := 0; i < 5; i++ {
+		if _, err := store.Inspect("suite:inspect", limit, window); err != nil {
+			t.Fatalf("Inspect: %v", err)
+		}
+	}
+	for i := 0; i < limit; i++ {
+		allowed, state, err := store.Allow("suite:inspect", limit, window)
+		if err != nil || !allowed {
+			t.Fatalf("expected inspecting not to consume the budget, request %d: allowed=%v err=%v", i+1, allowed, err)
+		}
+		if want := limit - i - 1; state.Remaining != want {
+			t.Fatalf("request %d: expected %d remaining, got %d", i+1, want, state.Remaining)
+		}
+		if state.Limit != limit || state.Reset <= 0 || state.Reset > window || state.RetryAfter != 0 {
+			t.Fatalf("request %d: unexpected state %+v", i+1, state)
+		}
+	}
+
+	state, err = store.Inspect("suite:inspect", limit, window)
+	if err != nil {
+		t.Fatalf("Inspect exhausted: %v", err)
+	}
+	if state.Remaining != 0 || state.Reset <= 0 || state.Reset > window {
+		t.Fatalf("expected exhausted budget, got %+v", state)
+	}
+
+	allowed, state, err := store.Allow("suite:inspect", limit, window)
+	if err != nil {
+		t.Fatalf("Allow over limit: %v", err)
+	}
+	if allowed || state.Remaining != 0 || state.RetryAfter <= 0 {
+		t.Fatalf("expected refusal with zero remaining and a retry delay, got allowed=%v %+v", allowed, state)
+	}
+}
+
+func TestMemoryStoreConformance(t *testing.T) {
+	runTokenStoreSuite(t, newMemoryStore())
+}
+
+func TestRateLimitHeadersAcrossBurst(t *testing.T) {
+	rl, err := newRateLimiter(RateLimitConfig{GlobalRPS: 0.001, GlobalBurst: 10})
+	if err != nil {
+		t.Fatalf("newRateLimiter error: %v", err)
+	}
+	handler := rateLimitMiddleware(rl, nil, nil, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	}))
+
+	const requests = 25
+	var (
+		mu        sync.Mutex
+		remaining []int
+		refused   int
+		wg        sync.WaitGroup
+	)
+	for i := 0; i < requests; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+			rec := httptest.NewRecorder()
+			handler.ServeHTTP(rec, req)
+			if rec.Header().Get("X-RateLimit-Limit") != "10" {
+				t.Errorf("expected limit header 10, got %q", rec.Header().Get("X-RateLimit-Limit"))
+			}
+			left, err := strconv.Atoi(rec.Header().Get("X-RateLimit-Remaining"))
+			if err != nil || left < 0 {
+				t.Errorf("expected non-negative remaining header, got %q", rec.Header().Get("X-RateLimit-Remaining"))
+			}
+			if reset, err := strconv.Atoi(rec.Header().Get("X-RateLimit-Reset")); err != nil || reset <= 0 {
+				t.Errorf("expected positive reset header, got %q", rec.Header().Get("X-RateLimit-Reset"))
+			}
+			mu.Lock()
+			defer mu.Unlock()
+			switch rec.Code {
+			case http.StatusOK:
+				remaining = append(remaining, left)
+			case http.StatusTooManyRequests:
+				refused++
+				if left != 0 || rec.Header().Get("Retry-After") == "" {
+					t.Errorf("expected refusal to report 0 remaining and Retry-After, got %q / %q", rec.Header().Get("X-RateLimit-Remaining"), rec.Header().Get("Retry-After"))
+				}
+			default:
+				t.Errorf("unexpected status %d", rec.Code)
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(remaining) != 10 || refused != requests-10 {
+		t.Fatalf("expected 10 allowed and %d refused, got %d and %d", requests-10, len(remaining), refused)
+	}
+	sort.Ints(remaining)
+	for i, left := range remaining {
+		if left != i {
+			t.Fatalf("expected each allowed request to see a distinct remaining count 0..9, got %v", remaining)
+		}
+	}
+}
+
+func TestRateLimitHeadersReportLoginBucket(t *testing.T) {
+	rl, err := newRateLimiter(RateLimitConfig{GlobalRPS: 100, GlobalBurst: 100, LoginLimit: 2, LoginWindow: time.Minute})
+	if err != nil {
+		t.Fatalf("newRateLimiter error: %v", err)
+	}
+	handler := rateLimitMiddleware(rl, nil, nil, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusNoContent)
+	}))
+
+	for i, want := range []string{"1", "0"} {
+		req := httptest.NewRequest(http.MethodPost, "/api/auth/login", nil)
+		req.RemoteAddr = "192.0.2.10:1234"
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusNoContent {
+			t.Fatalf("attempt %d: expected 204, got %d", i+1, rec.Code)
+		}
+		if rec.Header().Get("X-RateLimit-Limit") != "2" || rec.Header().Get("X-RateLimit-Remaining") != want {
+			t.Fatalf("attempt %d: expected login bucket headers 2/%s, got %q/%q", i+1, want, rec.Header().Get("X-RateLimit-Limit"), rec.Header().Get("X-RateLimit-Remaining"))
+		}
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, req)
+	if rec.Header().Get("X-RateLimit-Limit") != "100" {
+		t.Fatalf("expected non-auth request to report the global bucket, got %q", rec.Header().Get("X-RateLimit-Limit"))
+	}
+}
+
+func TestRateLimitInspectDoesNotConsume(t *testing.T) {
+	rl, err := newRateLimiter(RateLimitConfig{GlobalRPS: 0.001, GlobalBurst: 2, LoginLimit: 3, LoginWindow: time.Minute})
+	if err != nil {
+		t.Fatalf("newRateLimiter error: %v", err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc(rateLimitInspectPath, rl.handleInspect(nil))
+	mux.HandleFunc("/api/users", func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusOK)
+	})
+	handler := rateLimitMiddleware(rl, nil, nil, mux)
+	user := models.User{ID: "user-1", DisplayName: "Viewer"}
+
+	inspect := func() rateLimitResponse {
+		t.Helper()
+		req := httptest.NewRequest(http.MethodGet, rateLimitInspectPath, nil)
+		req.RemoteAddr = "192.0.2.20:1234"
+		req = req.WithContext(api.ContextWithUser(req.Context(), user))
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, req)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("expected 200, got %d: %s", rec.Code, rec.Body.String())
+		}
+		var resp rateLimitResponse
+		if err := json.Unmarshal(rec.Body.Bytes(), &resp); err != nil {
+			t.Fatalf("decode response: %v", err)
+		}
+		return resp
+	}
+
+	for i := 0; i < 5; i++ {
+		resp := inspect()
+		if len(resp.Buckets) != 2 {
+			t.Fatalf("expected global and login buckets, got %+v", resp.Buckets)
+		}
+		global, login := resp.Buckets[0], resp.Buckets[1]
+		if global.Name != rateLimitBucketGlobal || global.Limit != 2 || global.Remaining != 2 {
+			t.Fatalf("inspect %d: unexpected global bucket %+v", i+1, global)
+		}
+		if login.Name != rateLimitBucketLogin || login.Limit != 3 || login.Remaining != 3 || login.ResetSeconds != 0 {
+			t.Fatalf("inspect %d: unexpected login bucket %+v", i+1, login)
+		}
+	}
+
+	for i := 0; i < 2; i++ {
+		rec := httptest.NewRecorder()
+		handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/api/users", nil))
+		if rec.Code != http.StatusOK {
+			t.Fatalf("request %d: expected the full global budget after inspecting, got %d", i+1, rec.Code)
+		}
+	}
+	resp := inspect()
+	if resp.Buckets[0].Remaining != 0 || resp.Buckets[0].ResetSeconds <= 0 {
+		t.Fatalf("expected inspect to reflect the spent global budget, got %+v", resp.Buckets[0])
+	}
+
+	rec := httptest.NewRecorder()
+	handler.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, rateLimitInspectPath, nil))
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected anonymous inspect to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestRateLimitInspectReportsUnavailableStore(t *testing.T) {
+	rl := &rateLimiter{loginLimit: 1, loginWindow: time.Minute, store: failingTokenStore{}}
+	req := httptest.NewRequest(http.MethodGet, rateLimitInspectPath, nil)
+	req = req.WithContext(api.ContextWithUser(req.Context(), models.User{ID: "user-1"}))
+	rec := httptest.NewRecorder()
+	rl.handleInspect(nil)(rec, req)
+	if rec.Code != http.StatusServiceUnavailable {
+		t.Fatalf("expected 503, got %d", rec.Code)
+	}
+}
+
+type failingTokenStore struct{}
+
+func (failingTokenStore) Allow(string, int, time.Duration) (bool, rateLimitState, error) {
+	return false, rateLimitState{}, fmt.Errorf("store offline")
+}
+
+func (failingTokenStore) Inspect(string, int, time.Duration) (rateLimitState, error) {
+	return rateLimitState{}, fmt.Errorf("store offline")
+}
@@ -78,16 +78,19 @@ func newRedisStore(cfg redisStoreConfig) (*redisStore, error) {
 	return &redisStore{client: client, timeout: timeout}, nil
 }
 
-func (s *redisStore) Allow(key string, limit int, window time.Duration) (bool, time.Duration, error) {
+// Allow counts a request in key's fixed window. INCR hands each concurrent
+// caller its own count, so the remaining budget reported alongside is exact
+// for that request.
+func (s *redisStore) Allow(key string, limit int, window time.Duration) (bool, rateLimitState, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
 	defer cancel()
 	countReply, err := s.client.Do(ctx, "INCR", key)
 	if err != nil {
-		return false, 0, err
+		return false, rateLimitState{}, err
 	}
 	count, err := toInt(countReply)
 	if err != nil {
-		return false, 0, err
+		return false, rateLimitState{}, err
 	}
 	if count == 1 {
 		seconds := int64(window / time.Second)
@@ -95,24 +98,59 @@ func (s *redisStore) Allow(key string, limit int, window time.Duration) (bool, t
 			seconds = 1
 		}
 		if _, err := s.client.Do(ctx, "EXPIRE", key, seconds); err != nil {
-			return false, 0, err
+			return false, rateLimitState{}, err
 		}
 	}
+	reset, err := s.windowReset(ctx, key, window)
+	if err != nil {
+		return false, rateLimitState{}, err
+	}
+	state := rateLimitState{Limit: limit, Remaining: max(limit-int(count), 0), Reset: reset}
 	if count <= int64(limit) {
-		return true, 0, nil
+		return true, state, nil
+	}
+	state.RetryAfter = reset
+	return false, state, nil
+}
+
+// Inspect reads key's window without counting a request. A key with no
+// window open reports the full budget.
+func (s *redisStore) Inspect(key string, limit int, window time.Duration) (rateLimitState, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), s.timeout)
+	defer cancel()
+	countReply, err := s.client.Do(ctx, "GET", key)
+	if err != nil {
+		return rateLimitState{}, err
+	}
+	if countReply == nil {
+		return rateLimitState{Limit: limit, Remaining: limit}, nil
+	}
+	count, err := toInt(countReply)
+	if err != nil {
+		return rateLimitState{}, err
 	}
+	reset, err := s.windowReset(ctx, key, window)
+	if err != nil {
+		return rateLimitState{}, err
+	}
+	return rateLimitState{Limit: limit, Remaining: max(limit-int(count), 0), Reset: reset}, nil
+}
+
+// windowReset returns how long key's window has left, assuming a full window
+// when the key carries no expiry.
+func (s *redisStore) windowReset(ctx context.Context, key string, window time.Duration) (time.Duration, error) {
 	ttlReply, err := s.client.Do(ctx, "TTL", key)
 	if err != nil {
-		return false, 0, err
+		return 0, err
 	}
 	ttl, err := toInt(ttlReply)
 	if err != nil {
-		return false, 0, err
+		return 0, err
 	}
 	if ttl < 0 {
-		return false, window, nil
+		return window, nil
 	}
-	return false, time.Duration(ttl) * time.Second, nil
+	return time.Duration(ttl) * time.Second, nil
 }
 
 func (s *redisStore) Close(context.Context) error {
@@ -122,6 +160,20 @@ func (s *redisStore) Close(context.Context) error {
 	return s.client.Close()
 }
 
+// PingRateLimitRedis connects to the Redis deployment that backs login
+// throttling, the read cache, and maintenance mode, and sends PING without
+// keeping the connection.
+func PingRateLimitRedis(ctx context.Context, cfg RateLimitConfig) error {
+	store, err := newRedisStore(cfg.redisStoreConfig())
+	if err != nil {
+		return err
+	}
+	defer func() {
+		_ = store.Close(ctx)
+	}()
+	return store.Ping(ctx)
+}
+
 func (s *redisStore) Ping(ctx context.Context) error {
 	if s == nil || s.client == nil {
 		return nil
@@ -47,22 +47,40 @@ func runRedisStoreIntegration(t *testing.T, useTLS bool) {
 	t.Cleanup(func() {
 		_ = store.Close(context.Background())
 	})
-	allowed, retry, err := store.Allow("login:test", 2, time.Second)
-	if err != nil || !allowed || retry != 0 {
-		t.Fatalf("first allow unexpected: allowed=%v retry=%v err=%v", allowed, retry, err)
+	allowed, state, err := store.Allow("login:test", 2, time.Second)
+	if err != nil || !allowed || state.RetryAfter != 0 {
+		t.Fatalf("first allow unexpected: allowed=%v state=%+v err=%v", allowed, state, err)
 	}
-	allowed, retry, err = store.Allow("login:test", 2, time.Second)
+	allowed, state, err = store.Allow("login:test", 2, time.Second)
 	if err != nil || !allowed {
-		t.Fatalf("second allow unexpected: allowed=%v retry=%v err=%v", allowed, retry, err)
+		t.Fatalf("second allow unexpected: allowed=%v state=%+v err=%v", allowed, state, err)
 	}
-	allowed, retry, err = store.Allow("login:test", 2, time.Second)
+	allowed, state, err = store.Allow("login:test", 2, time.Second)
 	if err != nil {
 		t.Fatalf("third allow err: %v", err)
 	}
 	if allowed {
 		t.Fatalf("expected throttle on third attempt")
 	}
-	if retry < 0 {
-		t.Fatalf("expected non-negative retry, got %v", retry)
+	if state.RetryAfter < 0 {
+		t.Fatalf("expected non-negative retry, got %v", state.RetryAfter)
 	}
 }
+
+func TestRedisStoreConformance(t *testing.T) {
+	srv, err := redisstub.Start(redisstub.Options{})
+	if err != nil {
+		t.Fatalf("start redis stub: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = srv.Close()
+	})
+	store, err := newRedisStore(redisStoreConfig{Addr: srv.Addr(), Timeout: time.Second})
+	if err != nil {
+		t.Fatalf("new redis store: %v", err)
+	}
+	t.Cleanup(func() {
+		_ = store.Close(context.Background())
+	})
+	runTokenStoreSuite(t, store)
+}
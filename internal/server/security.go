@@ -1,9 +1,13 @@
 package server
 
-import "net/http"
+import (
+	"net/http"
+	"strings"
+)
 
 const (
 	defaultFrameAncestors     = "'none'"
+	defaultEmbedAncestors     = "*"
 	defaultFrameOptions       = "DENY"
 	defaultReferrerPolicy     = "no-referrer"
 	defaultPermissionsPolicy  = "camera=(), microphone=(), geolocation=()"
@@ -14,9 +18,12 @@ const (
 // against clickjacking, MIME sniffing, referrer leakage, and unintended
 // resource loading. Zero-valued fields fall back to safe defaults; override the
 // ContentSecurityPolicy directive when embedding the app in a trusted host.
+// EmbedFrameAncestors applies only to the /embed/ player, which third-party
+// sites may frame by default while the rest of the app stays unframeable.
 type SecurityConfig struct {
 	ContentSecurityPolicy string
 	FrameAncestors        string
+	EmbedFrameAncestors   string
 	FrameOptions          string
 	ReferrerPolicy        string
 	PermissionsPolicy     string
@@ -27,6 +34,7 @@ func defaultSecurityConfig() SecurityConfig {
 	return SecurityConfig{
 		ContentSecurityPolicy: defaultContentSecurityPolicy(defaultFrameAncestors),
 		FrameAncestors:        defaultFrameAncestors,
+		EmbedFrameAncestors:   defaultEmbedAncestors,
 		FrameOptions:          defaultFrameOptions,
 		ReferrerPolicy:        defaultReferrerPolicy,
 		PermissionsPolicy:     defaultPermissionsPolicy,
@@ -40,6 +48,9 @@ func (cfg SecurityConfig) withDefaults() SecurityConfig {
 	if cfg.FrameAncestors == "" {
 		cfg.FrameAncestors = defaults.FrameAncestors
 	}
+	if cfg.EmbedFrameAncestors == "" {
+		cfg.EmbedFrameAncestors = defaults.EmbedFrameAncestors
+	}
 	if cfg.FrameOptions == "" {
 		cfg.FrameOptions = defaults.FrameOptions
 	}
@@ -77,15 +88,40 @@ func defaultContentSecurityPolicy(frameAncestors string) string {
 		"form-action 'self'"
 }
 
+// embedContentSecurityPolicy locks the embed page down to inline styles and
+// media from any origin, since playback and thumbnails are usually served
+// from a CDN, while letting frameAncestors frame it.
+func embedContentSecurityPolicy(frameAncestors string) string {
+	return "default-src 'none'; " +
+		"img-src * data:; " +
+		"media-src * blob:; " +
+		"style-src 'unsafe-inline'; " +
+		"base-uri 'none'; " +
+		"form-action 'none'; " +
+		"frame-ancestors " + frameAncestors
+}
+
+// isEmbedPath reports whether path is the framable embed player.
+func isEmbedPath(path string) bool {
+	return strings.HasPrefix(path, "/embed/")
+}
+
 func securityHeadersMiddleware(cfg SecurityConfig, next http.Handler) http.Handler {
 	effective := cfg.withDefaults()
+	embedPolicy := embedContentSecurityPolicy(effective.EmbedFrameAncestors)
 
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if effective.ContentSecurityPolicy != "" {
-			w.Header().Set("Content-Security-Policy", effective.ContentSecurityPolicy)
-		}
-		if effective.FrameOptions != "" {
-			w.Header().Set("X-Frame-Options", effective.FrameOptions)
+		if isEmbedPath(r.URL.Path) {
+			// X-Frame-Options cannot allow arbitrary origins, so the embed
+			// relies on frame-ancestors alone.
+			w.Header().Set("Content-Security-Policy", embedPolicy)
+		} else {
+			if effective.ContentSecurityPolicy != "" {
+				w.Header().Set("Content-Security-Policy", effective.ContentSecurityPolicy)
+			}
+			if effective.FrameOptions != "" {
+				w.Header().Set("X-Frame-Options", effective.FrameOptions)
+			}
 		}
 		if effective.ContentTypeOptions != "" {
 			w.Header().Set("X-Content-Type-Options", effective.ContentTypeOptions)
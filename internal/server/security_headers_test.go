@@ -3,7 +3,11 @@ package server
 import (
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"strings"
 	"testing"
+
+	"bitriver-live/internal/storage"
 )
 
 func TestSecurityHeadersMiddlewareUsesDefaults(t *testing.T) {
@@ -118,6 +122,75 @@ func TestServerAppliesConfiguredSecurityHeaders(t *testing.T) {
 	assertHeaderEquals(t, res, "X-Content-Type-Options", customHeaders.ContentTypeOptions)
 }
 
+func TestServerAllowsFramingOnlyForEmbedRoute(t *testing.T) {
+	handler, store := newTestHandler(t)
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Embeddable", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+
+	srv, err := New(handler, Config{Addr: "127.0.0.1:0"})
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+
+	serve := func(path string) *http.Response {
+		rec := httptest.NewRecorder()
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.Header.Set("Origin", "https://blog.example")
+		srv.httpServer.Handler.ServeHTTP(rec, req)
+		return rec.Result()
+	}
+
+	embed := serve("/embed/" + channel.ID)
+	if embed.StatusCode != http.StatusOK {
+		t.Fatalf("expected embed page, got %d", embed.StatusCode)
+	}
+	assertHeaderEquals(t, embed, "Content-Security-Policy", embedContentSecurityPolicy(defaultEmbedAncestors))
+	assertHeaderEquals(t, embed, "X-Frame-Options", "")
+	assertHeaderEquals(t, embed, "Access-Control-Allow-Origin", "*")
+	if !strings.Contains(embed.Header.Get("Content-Security-Policy"), "frame-ancestors *") {
+		t.Fatalf("expected embed route to allow any frame ancestor")
+	}
+
+	oembed := serve("/api/oembed?url=" + url.QueryEscape("https://live.example/channels/"+channel.ID))
+	if oembed.StatusCode != http.StatusOK {
+		t.Fatalf("expected anonymous cross-origin oembed, got %d", oembed.StatusCode)
+	}
+	assertHeaderEquals(t, oembed, "Access-Control-Allow-Origin", "*")
+	assertHeaderEquals(t, oembed, "Access-Control-Allow-Credentials", "")
+	assertHeaderEquals(t, oembed, "X-Frame-Options", defaultFrameOptions)
+
+	// The main app stays unframeable and keeps rejecting unknown origins.
+	main := serve("/")
+	assertHeaderEquals(t, main, "Content-Security-Policy", defaultContentSecurityPolicy(defaultFrameAncestors))
+	assertHeaderEquals(t, main, "X-Frame-Options", defaultFrameOptions)
+	if main.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected unknown origin to be rejected on the main app, got %d", main.StatusCode)
+	}
+}
+
+func TestEmbedFrameAncestorsCanBeRestricted(t *testing.T) {
+	t.Parallel()
+
+	cfg := SecurityConfig{EmbedFrameAncestors: "https://partner.example"}
+	middleware := securityHeadersMiddleware(cfg, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+
+	rec := httptest.NewRecorder()
+	middleware.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/embed/channel", nil))
+	if got := rec.Header().Get("Content-Security-Policy"); !strings.HasSuffix(got, "frame-ancestors https://partner.example") {
+		t.Fatalf("expected configured embed frame ancestors, got %q", got)
+	}
+
+	rec = httptest.NewRecorder()
+	middleware.ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/viewer", nil))
+	assertDefaultSecurityHeaders(t, rec.Result())
+}
+
 func assertDefaultSecurityHeaders(t *testing.T, res *http.Response) {
 	t.Helper()
 	assertHeaderEquals(t, res, "Content-Security-Policy", defaultContentSecurityPolicy(defaultFrameAncestors))
@@ -3,11 +3,12 @@ package server
 import (
 	"context"
 	"crypto/subtle"
-	"crypto/tls"
+	"crypto/x509"
 	"errors"
 	"fmt"
 	"io/fs"
 	"log/slog"
+	"math"
 	"net"
 	"net/http"
 	"net/http/httputil"
@@ -16,6 +17,7 @@ import (
 	"time"
 
 	"bitriver-live/internal/api"
+	"bitriver-live/internal/auth"
 	"bitriver-live/internal/auth/oauth"
 	"bitriver-live/internal/observability/logging"
 	"bitriver-live/internal/observability/metrics"
@@ -24,10 +26,16 @@ import (
 
 // TLSConfig defines certificate files that enable TLS for the HTTP listener
 // created by Server. When both CertFile and KeyFile are provided the server
-// starts with TLS; otherwise it falls back to plain HTTP on Config.Addr.
+// starts with TLS; otherwise it falls back to plain HTTP on Config.Addr. The
+// files are polled every ReloadInterval (one minute when zero) and a changed
+// pair is validated and swapped in without a restart. ClientCAFile, when set,
+// requires /api/admin/* requests to present a client certificate signed by
+// one of the CAs in the PEM bundle.
 type TLSConfig struct {
-	CertFile string
-	KeyFile  string
+	CertFile       string
+	KeyFile        string
+	ReloadInterval time.Duration
+	ClientCAFile   string
 }
 
 // MetricsAccessConfig defines the authentication and network allowlist used to
@@ -46,8 +54,15 @@ type MetricsAccessConfig struct {
 // restricts the Prometheus scrape endpoint, ViewerOrigin configures reverse
 // proxying for viewer traffic, OAuth is injected into the supplied API handler,
 // SessionCookieSecureMode forces HTTPS-only session cookies when set to
-// SessionCookieSecureAlways, and SessionCookieCrossSite enables SameSite=None
-// cookies for cross-site viewer deployments.
+// SessionCookieSecureAlways, SessionCookieCrossSite enables SameSite=None
+// cookies for cross-site viewer deployments, Maintenance starts the platform in
+// read-only mode (shared through the rate-limit Redis when configured), and
+// ReadCache tunes or disables the cache in front of public directory and
+// channel reads (also shared through the rate-limit Redis when configured).
+// ProvisioningToken enables the identity-provider user provisioning API under
+// /api/admin/provisioning/, which accepts only that token. IngestEventsToken
+// likewise enables the ingest server's media events at
+// /api/internal/ingest/events.
 type Config struct {
 	Addr                    string
 	TLS                     TLSConfig
@@ -64,6 +79,10 @@ type Config struct {
 	SessionCookieSecureMode api.SessionCookieSecureMode
 	SessionCookieCrossSite  bool
 	SRSHookToken            string
+	ProvisioningToken       string
+	IngestEventsToken       string
+	Maintenance             MaintenanceConfig
+	ReadCache               ReadCacheConfig
 }
 
 // Server wraps the configured http.Server alongside observability, rate
@@ -75,9 +94,9 @@ type Server struct {
 	auditLogger *slog.Logger
 	metrics     *metrics.Recorder
 	rateLimiter *rateLimiter
+	maintenance *maintenanceController
 	ipResolver  *clientIPResolver
-	tlsCertFile string
-	tlsKeyFile  string
+	tlsReloader *certReloader
 }
 
 // New wires the HTTP router, middlewares, and instrumentation required for the
@@ -112,6 +131,8 @@ func New(handler *api.Handler, cfg Config) (*Server, error) {
 		handler.AllowSelfSignup = *cfg.AllowSelfSignup
 	}
 	handler.SRSHookToken = cfg.SRSHookToken
+	handler.ProvisioningToken = cfg.ProvisioningToken
+	handler.IngestEventsToken = cfg.IngestEventsToken
 	handler.SessionCookiePolicy = api.DefaultSessionCookiePolicy()
 	if cfg.SessionCookieSecureMode != 0 {
 		handler.SessionCookiePolicy.SecureMode = cfg.SessionCookieSecureMode
@@ -137,12 +158,64 @@ func New(handler *api.Handler, cfg Config) (*Server, error) {
 		return nil, fmt.Errorf("configure metrics access: %w", err)
 	}
 
+	var tlsReloader *certReloader
+	var clientCAs *x509.CertPool
+	certFile := strings.TrimSpace(cfg.TLS.CertFile)
+	keyFile := strings.TrimSpace(cfg.TLS.KeyFile)
+	clientCAFile := strings.TrimSpace(cfg.TLS.ClientCAFile)
+	if certFile != "" && keyFile != "" {
+		tlsReloader, err = newCertReloader(certFile, keyFile, cfg.TLS.ReloadInterval, cfg.Logger, recorder)
+		if err != nil {
+			return nil, fmt.Errorf("configure tls: %w", err)
+		}
+		if clientCAFile != "" {
+			clientCAs, err = loadClientCAs(clientCAFile)
+			if err != nil {
+				return nil, fmt.Errorf("configure tls: %w", err)
+			}
+		}
+	} else if clientCAFile != "" {
+		return nil, errors.New("configure tls: client ca requires a certificate and key")
+	}
+
+	var maintenanceBackend maintenanceStore
+	if cfg.RateLimit.redisConfigured() {
+		redisBackend, err := newRedisStore(cfg.RateLimit.redisStoreConfig())
+		if err != nil {
+			return nil, fmt.Errorf("configure maintenance store: %w", err)
+		}
+		maintenanceBackend = redisBackend
+	}
+	maintenance := newMaintenanceController(maintenanceBackend, cfg.Logger)
+	if cfg.Maintenance.Enabled {
+		seeded, err := maintenance.Seed(context.Background(), cfg.Maintenance.Reason, "config")
+		if err != nil {
+			return nil, fmt.Errorf("enable maintenance mode: %w", err)
+		}
+		if !seeded && cfg.Logger != nil {
+			cfg.Logger.Info("maintenance state already stored; leaving it unchanged")
+		}
+	}
+	if handler.ChatGateway != nil {
+		handler.ChatGateway.SetWriteGuard(maintenance.chatWriteGuard)
+	}
+	readCache, err := newReadCache(cfg.ReadCache, cfg.RateLimit, recorder)
+	if err != nil {
+		return nil, fmt.Errorf("configure read cache: %w", err)
+	}
+	handler.ReadCache = readCache
+	if handler.AuditLogger == nil {
+		handler.AuditLogger = cfg.AuditLogger
+	}
+
 	mux := http.NewServeMux()
 	mux.HandleFunc("/healthz", handler.Health)
 	mux.HandleFunc("/readyz", handler.Ready)
 	metricsHandler := recorder.Handler()
 	metricsHandler = metricsAccess.handler(metricsHandler)
 	mux.Handle("/metrics", metricsHandler)
+	// API routes are registered once under /api/; apiVersionMiddleware
+	// serves the same handlers under /api/v1/.
 	mux.HandleFunc("/api/auth/signup", handler.Signup)
 	mux.HandleFunc("/api/auth/login", handler.Login)
 	mux.HandleFunc("/api/auth/oauth/providers", handler.OAuthProviders)
@@ -150,6 +223,7 @@ func New(handler *api.Handler, cfg Config) (*Server, error) {
 	mux.HandleFunc("/api/auth/session", handler.Session)
 	mux.HandleFunc("/api/users", handler.Users)
 	mux.HandleFunc("/api/users/", handler.UserByID)
+	mux.HandleFunc(rateLimitInspectPath, rl.handleInspect(ipResolver))
 	mux.HandleFunc("/api/directory", handler.Directory)
 	mux.HandleFunc("/api/directory/featured", handler.DirectoryFeatured)
 	mux.HandleFunc("/api/directory/recommended", handler.DirectoryRecommended)
@@ -161,6 +235,7 @@ func New(handler *api.Handler, cfg Config) (*Server, error) {
 	mux.HandleFunc("/api/channels/", handler.ChannelByID)
 	mux.HandleFunc("/api/profiles", handler.Profiles)
 	mux.HandleFunc("/api/profiles/", handler.ProfileByID)
+	mux.HandleFunc("/api/badges", handler.Badges)
 	mux.HandleFunc("/api/chat/ws", handler.ChatWebsocket)
 	mux.HandleFunc("/api/recordings", handler.Recordings)
 	mux.HandleFunc("/api/recordings/", handler.RecordingByID)
@@ -169,18 +244,42 @@ func New(handler *api.Handler, cfg Config) (*Server, error) {
 	mux.HandleFunc("/api/moderation/queue", handler.ModerationQueue)
 	mux.HandleFunc("/api/moderation/queue/", handler.ModerationQueueByID)
 	mux.HandleFunc("/api/analytics/overview", handler.AnalyticsOverview)
+	mux.HandleFunc("/api/stats", handler.PlatformStats)
 	mux.HandleFunc("/api/ingest/srs-hook", handler.SRSHook)
+	mux.HandleFunc("/api/internal/ingest/events", handler.IngestEvents)
+	mux.HandleFunc("/api/playback/authorize", handler.PlaybackAuthorize)
+	mux.HandleFunc("/api/playback-preferences", handler.PlaybackPreferences)
+	mux.HandleFunc("/api/config", handler.Config)
+	mux.HandleFunc("/api/oembed", handler.OEmbed)
+	mux.HandleFunc("/embed/", handler.Embed)
+	mux.HandleFunc("/api/maintenance", maintenance.handleStatus)
+	mux.HandleFunc("/api/admin/maintenance", maintenance.handleAdmin)
+	mux.HandleFunc("/api/admin/channels/export", handler.AdminChannelsExport)
+	mux.HandleFunc("/api/admin/channels/batch", handler.AdminChannelsBatch)
+	mux.HandleFunc("/api/admin/health/components", handler.AdminComponentHealth)
+	mux.HandleFunc("/api/admin/stats", handler.AdminPlatformStats)
+	mux.HandleFunc("/api/admin/logging", handler.AdminLogging)
+	mux.HandleFunc("/api/admin/badges", handler.AdminBadges)
+	mux.HandleFunc("/api/admin/badges/", handler.AdminBadgeBySlug)
+	mux.HandleFunc("/api/admin/feature-flags", handler.AdminFeatureFlags)
+	mux.HandleFunc("/api/admin/feature-flags/", handler.AdminFeatureFlagByKey)
+	mux.HandleFunc("/api/admin/provisioning/users", handler.ProvisioningUsers)
+	mux.HandleFunc("/api/admin/provisioning/users/", handler.ProvisioningUserByID)
+	mux.HandleFunc("/api/admin/users/", handler.AdminUserByID)
 
 	staticFS, err := web.Static()
 	if err != nil {
 		return nil, fmt.Errorf("load web assets: %w", err)
 	}
-	index, err := fs.ReadFile(staticFS, "index.html")
+	assets, err := loadStaticAssets(staticFS)
 	if err != nil {
-		return nil, fmt.Errorf("read web index: %w", err)
+		return nil, fmt.Errorf("load web assets: %w", err)
+	}
+	index, ok := assets.entryPoint("index.html")
+	if !ok {
+		return nil, fmt.Errorf("read web index: %w", fs.ErrNotExist)
 	}
-	fileServer := http.FileServer(http.FS(staticFS))
-	mux.Handle("/static/", http.StripPrefix("/static/", fileServer))
+	mux.Handle("/static/", http.StripPrefix("/static/", assets))
 
 	if cfg.ViewerOrigin != nil {
 		viewerProxy := httputil.NewSingleHostReverseProxy(cfg.ViewerOrigin)
@@ -197,15 +296,20 @@ func New(handler *api.Handler, cfg Config) (*Server, error) {
 		mux.Handle("/viewer/", viewerHandler)
 	}
 
-	mux.HandleFunc("/", spaHandler(staticFS, index, fileServer, cfg.Logger, ipResolver))
+	mux.HandleFunc("/", spaHandler(staticFS, index, assets, cfg.Logger, ipResolver))
 
 	handlerChain := http.Handler(mux)
 	handlerChain = corsMiddleware(corsPolicy, cfg.Logger, handlerChain)
 	securityCfg := cfg.Security.withDefaults()
 	handlerChain = securityHeadersMiddleware(securityCfg, handlerChain)
 	handlerChain = requestIDMiddleware(cfg.Logger, handlerChain)
+	handlerChain = maintenanceMiddleware(maintenance, handlerChain)
+	handlerChain = impersonationGuardMiddleware(handlerChain)
+	handlerChain = featureFlagsMiddleware(handler, handlerChain)
 	handlerChain = authMiddleware(handler, handlerChain)
+	handlerChain = adminClientCertMiddleware(clientCAs != nil, cfg.Logger, ipResolver, handlerChain)
 	handlerChain = rateLimitMiddleware(rl, ipResolver, cfg.Logger, handlerChain)
+	handlerChain = apiVersionMiddleware(handlerChain)
 	handlerChain = metrics.HTTPMiddleware(recorder, handlerChain)
 	handlerChain = auditMiddleware(cfg.AuditLogger, ipResolver, handlerChain)
 	handlerChain = loggingMiddleware(cfg.Logger, ipResolver, handlerChain)
@@ -225,13 +329,13 @@ func New(handler *api.Handler, cfg Config) (*Server, error) {
 		auditLogger: cfg.AuditLogger,
 		metrics:     recorder,
 		rateLimiter: rl,
+		maintenance: maintenance,
 		ipResolver:  ipResolver,
-		tlsCertFile: strings.TrimSpace(cfg.TLS.CertFile),
-		tlsKeyFile:  strings.TrimSpace(cfg.TLS.KeyFile),
+		tlsReloader: tlsReloader,
 	}
 
-	if srv.tlsCertFile != "" && srv.tlsKeyFile != "" {
-		httpServer.TLSConfig = &tls.Config{MinVersion: tls.VersionTLS12}
+	if tlsReloader != nil {
+		httpServer.TLSConfig = newServerTLSConfig(tlsReloader, clientCAs)
 	}
 
 	return srv, nil
@@ -242,14 +346,18 @@ func (s *Server) Start() error {
 		return fmt.Errorf("http server is not configured")
 	}
 
-	if s.tlsCertFile != "" && s.tlsKeyFile != "" {
-		return s.httpServer.ListenAndServeTLS(s.tlsCertFile, s.tlsKeyFile)
+	if s.tlsReloader != nil {
+		go s.tlsReloader.run()
+		return s.httpServer.ListenAndServeTLS("", "")
 	}
 
 	return s.httpServer.ListenAndServe()
 }
 
 func (s *Server) Shutdown(ctx context.Context) error {
+	if s.tlsReloader != nil {
+		s.tlsReloader.Stop()
+	}
 	if s.httpServer == nil {
 		return nil
 	}
@@ -349,14 +457,21 @@ func rateLimitMiddleware(rl *rateLimiter, resolver *clientIPResolver, logger *sl
 		return next
 	}
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		if !rl.AllowRequest() {
+		if r.URL.Path == rateLimitInspectPath || isChannelStatusRequest(r) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		allowed, state := rl.AllowRequest()
+		setRateLimitHeaders(w, state)
+		if !allowed {
+			setRetryAfter(w, state.RetryAfter)
 			writeMiddlewareError(w, http.StatusTooManyRequests, "global rate limit exceeded")
 			return
 		}
 		if shouldRateLimitAuthRequest(r) {
 			ip, _ := resolveClientIP(r, resolver)
 			requestLogger := loggingWithRequest(logger, resolver, r)
-			allowed, retryAfter, err := rl.AllowLogin(ip)
+			allowed, state, err := rl.AllowLogin(ip)
 			if err != nil {
 				if requestLogger != nil {
 					requestLogger.Error("rate limiter failure", "error", err)
@@ -364,13 +479,14 @@ func rateLimitMiddleware(rl *rateLimiter, resolver *clientIPResolver, logger *sl
 				writeMiddlewareError(w, http.StatusServiceUnavailable, "rate limit failure")
 				return
 			}
+			// The login bucket is the narrower of the two, so its figures
+			// replace the global ones on authentication requests.
+			setRateLimitHeaders(w, state)
 			if !allowed {
 				if requestLogger != nil {
 					requestLogger.Warn("login rate limited")
 				}
-				if retryAfter > 0 {
-					w.Header().Set("Retry-After", fmt.Sprintf("%.0f", retryAfter.Seconds()))
-				}
+				setRetryAfter(w, state.RetryAfter)
 				writeMiddlewareError(w, http.StatusTooManyRequests, "too many login attempts")
 				return
 			}
@@ -379,6 +495,12 @@ func rateLimitMiddleware(rl *rateLimiter, resolver *clientIPResolver, logger *sl
 	})
 }
 
+func setRetryAfter(w http.ResponseWriter, retryAfter time.Duration) {
+	if retryAfter > 0 {
+		w.Header().Set("Retry-After", fmt.Sprintf("%.0f", math.Ceil(retryAfter.Seconds())))
+	}
+}
+
 func shouldRateLimitAuthRequest(r *http.Request) bool {
 	if r == nil || r.URL == nil {
 		return false
@@ -388,6 +510,8 @@ func shouldRateLimitAuthRequest(r *http.Request) bool {
 		return r.Method == http.MethodPost
 	case "/api/auth/session":
 		return r.Method == http.MethodGet || r.Method == http.MethodDelete
+	case "/api/users/me/password":
+		return r.Method == http.MethodPost
 	}
 
 	if strings.HasPrefix(r.URL.Path, "/api/auth/oauth/") {
@@ -411,13 +535,13 @@ func auditMiddleware(logger *slog.Logger, resolver *clientIPResolver, next http.
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		sr := metrics.NewResponseRecorder(w)
 		start := time.Now()
-		next.ServeHTTP(sr, r)
+		ip, source := resolveClientIP(r, resolver)
+		ctx, identity := withAuditIdentity(api.ContextWithClientIP(r.Context(), ip))
+		next.ServeHTTP(sr, r.WithContext(ctx))
 		if !shouldAudit(r) {
 			return
 		}
 		duration := time.Since(start)
-		user, ok := api.UserFromContext(r.Context())
-		ip, source := resolveClientIP(r, resolver)
 		requestLogger := loggerWithRequestContext(r.Context(), logger)
 		if requestLogger == nil {
 			return
@@ -430,8 +554,11 @@ func auditMiddleware(logger *slog.Logger, resolver *clientIPResolver, next http.
 			"remote_ip", ip,
 			"ip_source", source,
 		}
-		if ok {
-			fields = append(fields, "user_id", user.ID)
+		if identity.userID != "" {
+			fields = append(fields, "user_id", identity.userID)
+		}
+		if identity.impersonatorID != "" {
+			fields = append(fields, "impersonator_id", identity.impersonatorID)
 		}
 		requestLogger.Info("audit", fields...)
 	})
@@ -562,14 +689,55 @@ func clientIP(remoteAddr string) string {
 	return host
 }
 
+// provisioningPathPrefix covers the identity-provider provisioning API, which
+// authenticates with its own token instead of a session.
+const provisioningPathPrefix = "/api/admin/provisioning/"
+
+// isChannelStatusRequest reports whether r polls a channel's live status,
+// either GET /api/channels/{id}/status or the batch GET /api/channels/status.
+// Bots and widgets poll these constantly; the payload is public and served
+// from memory, so the requests skip session lookup and the global rate
+// limiter.
+func isChannelStatusRequest(r *http.Request) bool {
+	if r.Method != http.MethodGet {
+		return false
+	}
+	rest, ok := strings.CutPrefix(r.URL.Path, "/api/channels/")
+	if !ok {
+		return false
+	}
+	parts := strings.Split(strings.TrimSuffix(rest, "/"), "/")
+	switch len(parts) {
+	case 1:
+		return parts[0] == "status"
+	case 2:
+		return parts[0] != "" && parts[0] != "by-slug" && parts[1] == "status"
+	}
+	return false
+}
+
+// featureFlagsMiddleware attaches the caller's feature flag evaluator to API
+// requests. It sits inside authMiddleware so signed-in users are bucketed by
+// their user ID.
+func featureFlagsMiddleware(handler *api.Handler, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasPrefix(r.URL.Path, "/api/") {
+			r = handler.WithFeatureFlags(r)
+		}
+		next.ServeHTTP(w, r)
+	})
+}
+
 func authMiddleware(handler *api.Handler, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		path := r.URL.Path
-		if path == "/healthz" || path == "/metrics" || path == "/api/ingest/srs-hook" || strings.HasPrefix(path, "/api/auth/") || !strings.HasPrefix(path, "/api/") {
+		if path == "/healthz" || path == "/metrics" || path == "/api/ingest/srs-hook" || path == "/api/internal/ingest/events" || path == "/api/playback/authorize" || path == "/api/maintenance" || path == "/api/oembed" || strings.HasPrefix(path, "/api/auth/") || strings.HasPrefix(path, provisioningPathPrefix) || isChannelStatusRequest(r) || !strings.HasPrefix(path, "/api/") {
 			next.ServeHTTP(w, r)
 			return
 		}
-		optionalAuth := false
+		// Anonymous viewers keep playback preferences in a cookie, so the
+		// endpoint serves every method with or without a session.
+		optionalAuth := path == "/api/playback-preferences"
 		if r.Method == http.MethodGet {
 			switch {
 			case path == "/api/directory":
@@ -582,6 +750,18 @@ func authMiddleware(handler *api.Handler, next http.Handler) http.Handler {
 				optionalAuth = true
 			case strings.HasPrefix(path, "/api/profiles/"):
 				optionalAuth = true
+			case path == "/api/badges":
+				optionalAuth = true
+			case path == "/api/config":
+				optionalAuth = true
+			case path == "/api/stats":
+				optionalAuth = true
+			case strings.HasPrefix(path, "/api/users/by-handle/"):
+				optionalAuth = true
+			case path == "/api/users/me/following/schedule.ics":
+				// Calendar apps authenticate with the feed token in the
+				// URL, which the handler checks.
+				optionalAuth = true
 			}
 		}
 		token := api.ExtractToken(r)
@@ -593,20 +773,76 @@ func authMiddleware(handler *api.Handler, next http.Handler) http.Handler {
 			api.WriteError(w, http.StatusUnauthorized, fmt.Errorf("missing session token"))
 			return
 		}
-		user, expiresAt, err := handler.AuthenticateRequest(r)
+		if api.IsAPIToken(token) {
+			// Personal access tokens are presented explicitly in the
+			// Authorization header, so they never ride along on cross-site
+			// requests and skip cookie refresh entirely.
+			user, apiToken, err := handler.AuthenticateAPIToken(r)
+			if err != nil {
+				if optionalAuth {
+					next.ServeHTTP(w, r)
+					return
+				}
+				api.WriteError(w, http.StatusUnauthorized, err)
+				return
+			}
+			scope, allowed := apiTokenScope(r)
+			if !allowed {
+				api.WriteError(w, http.StatusForbidden, fmt.Errorf("access tokens cannot be used for this request"))
+				return
+			}
+			if !apiToken.HasScope(scope) {
+				api.WriteError(w, http.StatusForbidden, fmt.Errorf("access token missing %s scope", scope))
+				return
+			}
+			recordAuditIdentity(r.Context(), user.ID, "")
+			ctx := api.ContextWithAPIToken(api.ContextWithUser(r.Context(), user), apiToken)
+			next.ServeHTTP(w, r.WithContext(ctx))
+			return
+		}
+		user, session, err := handler.AuthenticateSession(r)
 		if err != nil {
-			if optionalAuth {
+			if errors.Is(err, auth.ErrSessionStoreUnavailable) {
+				// Keep the cookie: the session may still be valid once the
+				// store recovers, so answer 503 rather than logging out.
+				api.WriteError(w, http.StatusServiceUnavailable, fmt.Errorf("session store unavailable"))
+				return
+			}
+			if api.UsesImpersonationCookie(r) {
+				// Only the impersonation ended; the administrator's own
+				// session cookie takes over on the next request.
+				handler.ClearImpersonationCookie(w, r)
+			} else if optionalAuth {
 				handler.ClearSessionCookie(w, r)
+			}
+			if optionalAuth {
 				next.ServeHTTP(w, r)
 				return
 			}
 			api.WriteError(w, http.StatusUnauthorized, err)
 			return
 		}
-		if _, err := r.Cookie("bitriver_session"); err == nil {
-			handler.RefreshSessionCookie(w, r, token, expiresAt)
-		}
 		ctx := api.ContextWithUser(r.Context(), user)
+		if session.Impersonated() {
+			// The administrator is checked on every request, and the
+			// request is refused outright if they cannot be resolved: an
+			// impersonated request without the marker would slip past
+			// impersonationGuardMiddleware. Impersonation sessions have a
+			// fixed lifetime, so their cookie is never refreshed.
+			admin, err := handler.Impersonator(session)
+			if err != nil {
+				_ = handler.Sessions.Revoke(token)
+				if api.UsesImpersonationCookie(r) {
+					handler.ClearImpersonationCookie(w, r)
+				}
+				api.WriteError(w, http.StatusUnauthorized, err)
+				return
+			}
+			ctx = api.ContextWithImpersonator(ctx, admin)
+		} else if _, err := r.Cookie("bitriver_session"); err == nil {
+			handler.RefreshSessionCookie(w, r, token, session.ExpiresAt)
+		}
+		recordAuditIdentity(r.Context(), user.ID, session.ImpersonatorID)
 		next.ServeHTTP(w, r.WithContext(ctx))
 	})
 }
@@ -671,6 +907,7 @@ func spaHandler(staticFS fs.FS, index []byte, fileServer http.Handler, logger *s
 		}
 
 		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.Header().Set("Cache-Control", revalidateCacheControl)
 		if r.Method == http.MethodHead {
 			w.WriteHeader(http.StatusOK)
 			return
@@ -146,6 +146,273 @@ func TestAuthMiddlewareAcceptsCookie(t *testing.T) {
 	}
 }
 
+func newAPITokenTestMux(handler *api.Handler) http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/users/", handler.UserByID)
+	mux.HandleFunc("/api/channels/", handler.ChannelByID)
+	return authMiddleware(handler, mux)
+}
+
+func TestAuthMiddlewareAcceptsAPIToken(t *testing.T) {
+	handler, store := newTestHandler(t)
+	user, err := store.CreateUser(storage.CreateUserParams{
+		DisplayName: "Bot Owner",
+		Email:       "bots@example.com",
+	})
+	if err != nil {
+		t.Fatalf("CreateUser error: %v", err)
+	}
+	_, secret, err := store.CreateAPIToken(storage.CreateAPITokenParams{UserID: user.ID, Name: "deck"})
+	if err != nil {
+		t.Fatalf("CreateAPIToken error: %v", err)
+	}
+
+	nextCalled := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		nextCalled = true
+		ctxUser, ok := api.UserFromContext(r.Context())
+		if !ok || ctxUser.ID != user.ID {
+			t.Fatalf("expected user %s in context, got %+v", user.ID, ctxUser)
+		}
+		token, ok := api.APITokenFromContext(r.Context())
+		if !ok || !token.HasScope(storage.APITokenScopeRead) {
+			t.Fatalf("expected read-scoped token in context, got %+v", token)
+		}
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users/"+user.ID, nil)
+	req.Header.Set("Authorization", "Bearer "+secret)
+	rec := httptest.NewRecorder()
+
+	authMiddleware(handler, next).ServeHTTP(rec, req)
+
+	if !nextCalled {
+		t.Fatalf("expected middleware to call next handler, got status %d", rec.Code)
+	}
+	if cookies := rec.Result().Cookies(); len(cookies) != 0 {
+		t.Fatalf("expected no cookies for token auth, got %v", cookies)
+	}
+}
+
+func TestAPITokenScopeEnforcedOnChannelUpdate(t *testing.T) {
+	handler, store := newTestHandler(t)
+	user, err := store.CreateUser(storage.CreateUserParams{
+		DisplayName: "Creator",
+		Email:       "creator@example.com",
+		Roles:       []string{"creator"},
+	})
+	if err != nil {
+		t.Fatalf("CreateUser error: %v", err)
+	}
+	channel, err := store.CreateChannel(user.ID, "Original", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel error: %v", err)
+	}
+	_, readOnly, err := store.CreateAPIToken(storage.CreateAPITokenParams{UserID: user.ID, Name: "reader", Scopes: []string{storage.APITokenScopeRead}})
+	if err != nil {
+		t.Fatalf("CreateAPIToken error: %v", err)
+	}
+	_, manager, err := store.CreateAPIToken(storage.CreateAPITokenParams{UserID: user.ID, Name: "manager", Scopes: []string{storage.APITokenScopeManageChannel}})
+	if err != nil {
+		t.Fatalf("CreateAPIToken error: %v", err)
+	}
+	mux := newAPITokenTestMux(handler)
+
+	patch := func(secret string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodPatch, "/api/channels/"+channel.ID, strings.NewReader(`{"title":"Updated"}`))
+		req.Header.Set("Authorization", "Bearer "+secret)
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec
+	}
+
+	if rec := patch(readOnly); rec.Code != http.StatusForbidden {
+		t.Fatalf("expected read-only token to be forbidden, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if rec := patch(manager); rec.Code != http.StatusOK {
+		t.Fatalf("expected manage-channel token to update channel, got %d: %s", rec.Code, rec.Body.String())
+	}
+	updated, ok := store.GetChannel(channel.ID)
+	if !ok || updated.Title != "Updated" {
+		t.Fatalf("expected channel title to be updated, got %+v", updated)
+	}
+
+	req := httptest.NewRequest(http.MethodGet, "/api/users/"+user.ID, nil)
+	req.Header.Set("Authorization", "Bearer "+manager)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	if rec.Code != http.StatusForbidden {
+		t.Fatalf("expected token without read scope to be forbidden on GET, got %d", rec.Code)
+	}
+}
+
+func TestAPITokenWritesRequireDeclaredScope(t *testing.T) {
+	handler, store := newTestHandler(t)
+	admin, err := store.CreateUser(storage.CreateUserParams{
+		DisplayName: "Admin",
+		Email:       "admin@example.com",
+		Roles:       []string{"admin"},
+	})
+	if err != nil {
+		t.Fatalf("CreateUser error: %v", err)
+	}
+	channel, err := store.CreateChannel(admin.ID, "Arena", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel error: %v", err)
+	}
+	_, readOnly, err := store.CreateAPIToken(storage.CreateAPITokenParams{UserID: admin.ID, Name: "reader", Scopes: []string{storage.APITokenScopeRead}})
+	if err != nil {
+		t.Fatalf("CreateAPIToken error: %v", err)
+	}
+	_, everything, err := store.CreateAPIToken(storage.CreateAPITokenParams{UserID: admin.ID, Name: "everything", Scopes: []string{storage.APITokenScopeRead, storage.APITokenScopeChat, storage.APITokenScopeManageChannel}})
+	if err != nil {
+		t.Fatalf("CreateAPIToken error: %v", err)
+	}
+	mux := http.NewServeMux()
+	mux.HandleFunc("/api/users/", handler.UserByID)
+	mux.HandleFunc("/api/channels", handler.Channels)
+	mux.HandleFunc("/api/channels/", handler.ChannelByID)
+	mux.HandleFunc("/api/uploads", handler.Uploads)
+	mux.HandleFunc("/api/admin/channels/batch", handler.AdminChannelsBatch)
+	mux.HandleFunc("/api/admin/badges", handler.AdminBadges)
+	chain := authMiddleware(handler, mux)
+
+	serve := func(method, path, body, secret string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+secret)
+		req.Header.Set("Content-Type", "application/json")
+		rec := httptest.NewRecorder()
+		chain.ServeHTTP(rec, req)
+		return rec
+	}
+
+	undeclared := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{method: http.MethodPatch, path: "/api/users/" + admin.ID, body: `{"displayName":"Renamed"}`},
+		{method: http.MethodDelete, path: "/api/users/" + admin.ID},
+		{method: http.MethodPost, path: "/api/channels/" + channel.ID + "/follow"},
+		{method: http.MethodPost, path: "/api/channels/" + channel.ID + "/subscribe", body: `{"tier":"tier1","provider":"stripe","reference":"tok-1","amount":4.99,"currency":"usd"}`},
+		{method: http.MethodPost, path: "/api/channels/" + channel.ID + "/subscribe/gift", body: `{"recipientId":"someone"}`},
+		{method: http.MethodPost, path: "/api/channels/" + channel.ID + "/chat/reports", body: `{"targetId":"someone","reason":"abuse"}`},
+		{method: http.MethodPost, path: "/api/admin/channels/batch", body: `{"channelIds":["` + channel.ID + `"],"category":"music"}`},
+		{method: http.MethodPost, path: "/api/admin/badges", body: `{"slug":"early","name":"Early"}`},
+	}
+	for _, secret := range []string{readOnly, everything} {
+		for _, tc := range undeclared {
+			if rec := serve(tc.method, tc.path, tc.body, secret); rec.Code != http.StatusForbidden {
+				t.Fatalf("expected token %s %s to be forbidden, got %d: %s", tc.method, tc.path, rec.Code, rec.Body.String())
+			}
+		}
+	}
+	if user, ok := store.GetUser(admin.ID); !ok || user.DisplayName != "Admin" {
+		t.Fatalf("expected the account to be unchanged, got %+v", user)
+	}
+	if store.IsFollowingChannel(admin.ID, channel.ID) {
+		t.Fatal("expected the follow to be refused")
+	}
+	if updated, ok := store.GetChannel(channel.ID); !ok || updated.Category != "gaming" {
+		t.Fatalf("expected the admin batch to be refused, got %+v", updated)
+	}
+
+	declared := []struct {
+		method string
+		path   string
+		body   string
+	}{
+		{method: http.MethodPost, path: "/api/channels", body: `{"ownerId":"` + admin.ID + `","title":"Second","category":"music"}`},
+		{method: http.MethodPatch, path: "/api/channels/" + channel.ID, body: `{"title":"Renamed"}`},
+		{method: http.MethodPost, path: "/api/uploads", body: `{"channelId":"` + channel.ID + `","title":"Clip"}`},
+	}
+	for _, tc := range declared {
+		if rec := serve(tc.method, tc.path, tc.body, readOnly); rec.Code != http.StatusForbidden {
+			t.Fatalf("expected read-only token %s %s to be forbidden, got %d: %s", tc.method, tc.path, rec.Code, rec.Body.String())
+		}
+		if rec := serve(tc.method, tc.path, tc.body, everything); rec.Code == http.StatusForbidden || rec.Code == http.StatusUnauthorized {
+			t.Fatalf("expected manage-channel token %s %s to be allowed, got %d: %s", tc.method, tc.path, rec.Code, rec.Body.String())
+		}
+	}
+}
+
+func TestAPITokenRevocationTakesEffectImmediately(t *testing.T) {
+	handler, store := newTestHandler(t)
+	user, err := store.CreateUser(storage.CreateUserParams{
+		DisplayName: "Bot Owner",
+		Email:       "bots@example.com",
+	})
+	if err != nil {
+		t.Fatalf("CreateUser error: %v", err)
+	}
+	session, _, err := handler.Sessions.Create(user.ID)
+	if err != nil {
+		t.Fatalf("Create session: %v", err)
+	}
+	mux := newAPITokenTestMux(handler)
+
+	createReq := httptest.NewRequest(http.MethodPost, "/api/users/me/tokens", strings.NewReader(`{"name":"deck","scopes":["read","chat"]}`))
+	createReq.AddCookie(&http.Cookie{Name: "bitriver_session", Value: session})
+	createRec := httptest.NewRecorder()
+	mux.ServeHTTP(createRec, createReq)
+	if createRec.Code != http.StatusCreated {
+		t.Fatalf("expected token creation to succeed, got %d: %s", createRec.Code, createRec.Body.String())
+	}
+	var created struct {
+		ID    string `json:"id"`
+		Token string `json:"token"`
+	}
+	if err := json.Unmarshal(createRec.Body.Bytes(), &created); err != nil {
+		t.Fatalf("decode token response: %v", err)
+	}
+	if created.Token == "" || created.ID == "" {
+		t.Fatalf("expected token secret and id, got %+v", created)
+	}
+
+	get := func() int {
+		req := httptest.NewRequest(http.MethodGet, "/api/users/"+user.ID, nil)
+		req.Header.Set("Authorization", "Bearer "+created.Token)
+		rec := httptest.NewRecorder()
+		mux.ServeHTTP(rec, req)
+		return rec.Code
+	}
+	if code := get(); code != http.StatusOK {
+		t.Fatalf("expected token to authenticate, got %d", code)
+	}
+
+	listReq := httptest.NewRequest(http.MethodGet, "/api/users/me/tokens", nil)
+	listReq.Header.Set("Authorization", "Bearer "+created.Token)
+	listRec := httptest.NewRecorder()
+	mux.ServeHTTP(listRec, listReq)
+	if listRec.Code != http.StatusForbidden {
+		t.Fatalf("expected token management via token to be forbidden, got %d", listRec.Code)
+	}
+
+	listReq = httptest.NewRequest(http.MethodGet, "/api/users/me/tokens", nil)
+	listReq.AddCookie(&http.Cookie{Name: "bitriver_session", Value: session})
+	listRec = httptest.NewRecorder()
+	mux.ServeHTTP(listRec, listReq)
+	if listRec.Code != http.StatusOK {
+		t.Fatalf("expected token list to succeed, got %d", listRec.Code)
+	}
+	if strings.Contains(listRec.Body.String(), created.Token) {
+		t.Fatal("expected token list to omit secrets")
+	}
+
+	revokeReq := httptest.NewRequest(http.MethodDelete, "/api/users/me/tokens/"+created.ID, nil)
+	revokeReq.AddCookie(&http.Cookie{Name: "bitriver_session", Value: session})
+	revokeRec := httptest.NewRecorder()
+	mux.ServeHTTP(revokeRec, revokeReq)
+	if revokeRec.Code != http.StatusNoContent {
+		t.Fatalf("expected revoke to succeed, got %d: %s", revokeRec.Code, revokeRec.Body.String())
+	}
+
+	if code := get(); code != http.StatusUnauthorized {
+		t.Fatalf("expected revoked token to be rejected, got %d", code)
+	}
+}
+
 func TestAuthMiddlewareRejectsMissingSession(t *testing.T) {
 	handler, _ := newTestHandler(t)
 	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -269,6 +536,29 @@ func TestAuthMiddlewareAllowsUnauthenticatedProfileGet(t *testing.T) {
 	}
 }
 
+func TestAuthMiddlewareAllowsAnonymousPlaybackPreferences(t *testing.T) {
+	handler, _ := newTestHandler(t)
+	handler.CookieSigningKey = []byte("0123456789abcdef0123456789abcdef")
+	next := http.HandlerFunc(handler.PlaybackPreferences)
+
+	req := httptest.NewRequest(http.MethodPatch, "/api/playback-preferences", strings.NewReader(`{"defaultQuality":"low"}`))
+	rec := httptest.NewRecorder()
+	authMiddleware(handler, next).ServeHTTP(rec, req)
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected anonymous PATCH to pass, got %d: %s", rec.Code, rec.Body.String())
+	}
+	if len(rec.Result().Cookies()) != 1 {
+		t.Fatalf("expected a preferences cookie, got %v", rec.Result().Cookies())
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/users/me/playback-preferences", nil)
+	rec = httptest.NewRecorder()
+	authMiddleware(handler, http.HandlerFunc(handler.UserByID)).ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected account preferences to require a session, got %d", rec.Code)
+	}
+}
+
 func TestAuthMiddlewareRejectsInvalidSession(t *testing.T) {
 	handler, _ := newTestHandler(t)
 	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -286,6 +576,80 @@ func TestAuthMiddlewareRejectsInvalidSession(t *testing.T) {
 	}
 }
 
+func TestAuthMiddlewareLeavesProvisioningToItsToken(t *testing.T) {
+	handler, _ := newTestHandler(t)
+	handler.ProvisioningToken = "provision-secret"
+	called := false
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+		if _, ok := api.UserFromContext(r.Context()); ok {
+			t.Fatal("expected no user on provisioning requests")
+		}
+		handler.ProvisioningUsers(w, r)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/api/admin/provisioning/users?email=a@example.com", nil)
+	req.Header.Set("Authorization", "Bearer provision-secret")
+	rec := httptest.NewRecorder()
+	authMiddleware(handler, next).ServeHTTP(rec, req)
+	if !called || rec.Code != http.StatusOK {
+		t.Fatalf("expected provisioning request to reach its handler, got called=%v status %d", called, rec.Code)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/api/users", nil)
+	req.Header.Set("Authorization", "Bearer provision-secret")
+	rec = httptest.NewRecorder()
+	authMiddleware(handler, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("unexpected call to next handler")
+	})).ServeHTTP(rec, req)
+	if rec.Code != http.StatusUnauthorized {
+		t.Fatalf("expected the provisioning token to be refused elsewhere, got %d", rec.Code)
+	}
+}
+
+type unavailableSessionStore struct{}
+
+func (unavailableSessionStore) Save(auth.SessionRecord) error {
+	return errors.New("connection refused")
+}
+
+func (unavailableSessionStore) Get(string) (auth.SessionRecord, bool, error) {
+	return auth.SessionRecord{}, false, errors.New("connection refused")
+}
+
+func (unavailableSessionStore) Delete(string) error { return errors.New("connection refused") }
+
+func (unavailableSessionStore) PurgeExpired(time.Time) error { return nil }
+
+func TestAuthMiddlewareReturnsUnavailableWhenSessionStoreFails(t *testing.T) {
+	dir := t.TempDir()
+	store, err := storage.NewStorage(filepath.Join(dir, "store.json"))
+	if err != nil {
+		t.Fatalf("NewStorage error: %v", err)
+	}
+	handler := api.NewHandler(store, auth.NewSessionManager(time.Hour, auth.WithStore(unavailableSessionStore{})))
+	next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Fatal("unexpected call to next handler")
+	})
+
+	for _, path := range []string{"/api/users", "/api/profiles"} {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.AddCookie(&http.Cookie{Name: "bitriver_session", Value: "session-token"})
+		rec := httptest.NewRecorder()
+
+		authMiddleware(handler, next).ServeHTTP(rec, req)
+
+		if rec.Code != http.StatusServiceUnavailable {
+			t.Fatalf("%s: expected status 503, got %d", path, rec.Code)
+		}
+		for _, c := range rec.Result().Cookies() {
+			if c.Name == "bitriver_session" {
+				t.Fatalf("%s: expected session cookie to be left alone, got %+v", path, c)
+			}
+		}
+	}
+}
+
 func TestClientIPResolverIgnoresForwardedByDefault(t *testing.T) {
 	resolver, err := newClientIPResolver(RateLimitConfig{})
 	if err != nil {
@@ -606,6 +970,7 @@ func TestRateLimitMiddlewareAuthPaths(t *testing.T) {
 		{name: "session delete", method: http.MethodDelete, path: "/api/auth/session"},
 		{name: "oauth start", method: http.MethodPost, path: "/api/auth/oauth/provider/start"},
 		{name: "oauth callback", method: http.MethodGet, path: "/api/auth/oauth/provider/callback"},
+		{name: "password change", method: http.MethodPost, path: "/api/users/me/password"},
 	}
 
 	for _, tc := range testCases {
@@ -706,6 +1071,53 @@ func TestRateLimitMiddlewareGlobalLimit(t *testing.T) {
 	}
 }
 
+func TestChannelStatusSkipsGlobalRateLimit(t *testing.T) {
+	t.Parallel()
+
+	handler, store := newTestHandler(t)
+	owner, err := store.CreateUser(storage.CreateUserParams{DisplayName: "Creator", Email: "creator@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Live", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	srv, err := New(handler, Config{RateLimit: RateLimitConfig{GlobalRPS: 0.001, GlobalBurst: 1}})
+	if err != nil {
+		t.Fatalf("New returned error: %v", err)
+	}
+	serve := func(path string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, path, nil)
+		req.RemoteAddr = "5.6.7.8:9999"
+		// A stale credential must not turn a public status poll into a 401.
+		req.Header.Set("Authorization", "Bearer expired-session")
+		rec := httptest.NewRecorder()
+		srv.httpServer.Handler.ServeHTTP(rec, req)
+		return rec
+	}
+
+	serve("/api/directory")
+	if rec := serve("/api/directory"); rec.Code != http.StatusTooManyRequests {
+		t.Fatalf("expected the global bucket to be exhausted, got %d", rec.Code)
+	}
+	for _, path := range []string{
+		"/api/channels/" + channel.ID + "/status",
+		"/api/v1/channels/" + channel.ID + "/status",
+		"/api/channels/status?ids=" + channel.ID + ",missing",
+	} {
+		for i := 0; i < 3; i++ {
+			rec := serve(path)
+			if rec.Code != http.StatusOK {
+				t.Fatalf("expected %s to bypass the global limit, got %d: %s", path, rec.Code, rec.Body.String())
+			}
+			if rec.Header().Get("X-RateLimit-Limit") != "" {
+				t.Fatalf("expected no rate limit headers on %s", path)
+			}
+		}
+	}
+}
+
 func TestMetricsAccessToken(t *testing.T) {
 	t.Parallel()
 
@@ -0,0 +1,316 @@
+package server
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"crypto/sha512"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"io/fs"
+	"mime"
+	"net/http"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const (
+	// immutableCacheControl is sent with fingerprinted assets, whose
+	// content never changes under the same path.
+	immutableCacheControl = "public, max-age=31536000, immutable"
+	// revalidateCacheControl is sent with entry points and assets requested
+	// by their plain name, so browsers check the ETag before reusing them.
+	revalidateCacheControl = "no-cache"
+	// fingerprintLength is how many hex digits of the content hash go into
+	// a fingerprinted file name.
+	fingerprintLength = 12
+)
+
+// precompressedEncodings lists the Content-Encodings a precompressed
+// sibling file may provide, in order of preference, with its extension.
+var precompressedEncodings = []struct {
+	name      string
+	extension string
+}{
+	{name: "br", extension: ".br"},
+	{name: "gzip", extension: ".gz"},
+}
+
+// staticAsset is one embedded file ready to serve.
+type staticAsset struct {
+	name        string
+	contentType string
+	body        []byte
+	etag        string
+	integrity   string
+	// fingerprinted is the content-addressed name the file is also served
+	// under. It is empty for entry points.
+	fingerprinted string
+	// variants holds precompressed bodies keyed by Content-Encoding.
+	variants map[string][]byte
+}
+
+// assetManifestEntry is where a logical asset name is served from and the
+// Subresource Integrity hash of its content.
+type assetManifestEntry struct {
+	Path      string `json:"path"`
+	Integrity string `json:"integrity"`
+}
+
+// staticAssets serves the embedded control centre. Every file except the
+// HTML entry points is also served under a fingerprinted name that embeds
+// its content hash and may be cached forever; the entry points reference
+// those names, so a deploy changes the HTML and nothing else needs to
+// expire.
+type staticAssets struct {
+	byName        map[string]*staticAsset
+	byFingerprint map[string]*staticAsset
+	manifest      map[string]assetManifestEntry
+}
+
+// loadStaticAssets reads every file in fsys, fingerprints the assets, and
+// rewrites the HTML entry points to reference them. Files ending in .br or
+// .gz next to an asset are served as its precompressed variants.
+func loadStaticAssets(fsys fs.FS) (*staticAssets, error) {
+	contents := make(map[string][]byte)
+	err := fs.WalkDir(fsys, ".", func(name string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		body, err := fs.ReadFile(fsys, name)
+		if err != nil {
+			return err
+		}
+		contents[name] = body
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	assets := &staticAssets{
+		byName:        make(map[string]*staticAsset),
+		byFingerprint: make(map[string]*staticAsset),
+		manifest:      make(map[string]assetManifestEntry),
+	}
+	names := make([]string, 0, len(contents))
+	for name := range contents {
+		if isPrecompressedVariant(name, contents) {
+			continue
+		}
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var entryPoints []string
+	for _, name := range names {
+		if isEntryPoint(name) {
+			entryPoints = append(entryPoints, name)
+			continue
+		}
+		asset := newStaticAsset(name, contents[name])
+		asset.fingerprinted = fingerprintedName(name, asset.etag)
+		for _, encoding := range precompressedEncodings {
+			if variant, ok := contents[name+encoding.extension]; ok {
+				if asset.variants == nil {
+					asset.variants = make(map[string][]byte)
+				}
+				asset.variants[encoding.name] = variant
+			}
+		}
+		assets.byName[name] = asset
+		assets.byFingerprint[asset.fingerprinted] = asset
+		assets.manifest[name] = assetManifestEntry{Path: "/static/" + asset.fingerprinted, Integrity: asset.integrity}
+	}
+	// Entry points are rewritten after every asset is fingerprinted, so
+	// precompressed copies of them would be stale and are ignored.
+	for _, name := range entryPoints {
+		assets.byName[name] = newStaticAsset(name, rewriteAssetReferences(contents[name], assets.manifest))
+	}
+	return assets, nil
+}
+
+func newStaticAsset(name string, body []byte) *staticAsset {
+	sum := sha256.Sum256(body)
+	integrity := sha512.Sum384(body)
+	contentType := mime.TypeByExtension(path.Ext(name))
+	if contentType == "" {
+		contentType = http.DetectContentType(body)
+	}
+	return &staticAsset{
+		name:        name,
+		contentType: contentType,
+		body:        body,
+		etag:        hex.EncodeToString(sum[:])[:fingerprintLength],
+		integrity:   "sha384-" + base64.StdEncoding.EncodeToString(integrity[:]),
+	}
+}
+
+// isEntryPoint reports whether name is an HTML page. Pages are requested by
+// a stable URL, so they are never fingerprinted or cached without
+// revalidation.
+func isEntryPoint(name string) bool {
+	return path.Ext(name) == ".html"
+}
+
+// isPrecompressedVariant reports whether name is a compressed copy of
+// another embedded file.
+func isPrecompressedVariant(name string, contents map[string][]byte) bool {
+	for _, encoding := range precompressedEncodings {
+		if base, ok := strings.CutSuffix(name, encoding.extension); ok {
+			if _, exists := contents[base]; exists {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// fingerprintedName inserts hash before the extension of name, so
+// "app.js" becomes "app.<hash>.js".
+func fingerprintedName(name, hash string) string {
+	ext := path.Ext(name)
+	return strings.TrimSuffix(name, ext) + "." + hash + ext
+}
+
+// Manifest returns the fingerprinted path and integrity hash of every
+// asset, keyed by its logical name such as "app.js".
+func (a *staticAssets) Manifest() map[string]assetManifestEntry {
+	manifest := make(map[string]assetManifestEntry, len(a.manifest))
+	for name, entry := range a.manifest {
+		manifest[name] = entry
+	}
+	return manifest
+}
+
+// entryPoint returns the rewritten body of an HTML page.
+func (a *staticAssets) entryPoint(name string) ([]byte, bool) {
+	asset, ok := a.byName[name]
+	if !ok || !isEntryPoint(name) {
+		return nil, false
+	}
+	return asset.body, true
+}
+
+// assetTagPattern matches an opening HTML tag, and assetReferencePattern a
+// src or href attribute pointing into /static/.
+var (
+	assetTagPattern       = regexp.MustCompile(`<[a-zA-Z][^>]*>`)
+	assetReferencePattern = regexp.MustCompile(`\b(src|href)="/static/([^"?#]+)"`)
+)
+
+// rewriteAssetReferences points /static/ references in html at the
+// fingerprinted names in manifest. Script and link tags also get an
+// integrity attribute unless they already carry one.
+func rewriteAssetReferences(html []byte, manifest map[string]assetManifestEntry) []byte {
+	return assetTagPattern.ReplaceAllFunc(html, func(tag []byte) []byte {
+		var integrity string
+		rewritten := assetReferencePattern.ReplaceAllFunc(tag, func(attr []byte) []byte {
+			match := assetReferencePattern.FindSubmatch(attr)
+			entry, ok := manifest[string(match[2])]
+			if !ok {
+				return attr
+			}
+			integrity = entry.Integrity
+			return []byte(fmt.Sprintf(`%s=%q`, match[1], entry.Path))
+		})
+		if integrity == "" || !wantsIntegrity(rewritten) {
+			return rewritten
+		}
+		end := len(rewritten) - 1
+		if bytes.HasSuffix(rewritten, []byte("/>")) {
+			end--
+		}
+		for end > 0 && rewritten[end-1] == ' ' {
+			end--
+		}
+		attribute := ` integrity="` + integrity + `"`
+		return append(append(append([]byte{}, rewritten[:end]...), attribute...), rewritten[end:]...)
+	})
+}
+
+func wantsIntegrity(tag []byte) bool {
+	lower := bytes.ToLower(tag)
+	if bytes.Contains(lower, []byte("integrity=")) {
+		return false
+	}
+	return bytes.HasPrefix(lower, []byte("<script")) || bytes.HasPrefix(lower, []byte("<link"))
+}
+
+// ServeHTTP serves the asset named by the request path, which is relative
+// to the asset root.
+func (a *staticAssets) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		http.Error(w, fmt.Sprintf("method %s not allowed", r.Method), http.StatusMethodNotAllowed)
+		return
+	}
+	name := strings.TrimPrefix(r.URL.Path, "/")
+	cacheControl := revalidateCacheControl
+	asset, ok := a.byFingerprint[name]
+	if ok {
+		cacheControl = immutableCacheControl
+	} else if asset, ok = a.byName[name]; !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	header := w.Header()
+	header.Set("Cache-Control", cacheControl)
+	header.Set("Content-Type", asset.contentType)
+	body, etag := asset.body, asset.etag
+	if len(asset.variants) > 0 {
+		header.Add("Vary", "Accept-Encoding")
+		if encoding := negotiateEncoding(r.Header.Get("Accept-Encoding"), asset.variants); encoding != "" {
+			header.Set("Content-Encoding", encoding)
+			body, etag = asset.variants[encoding], etag+"-"+encoding
+		}
+	}
+	header.Set("ETag", strconv.Quote(etag))
+	http.ServeContent(w, r, asset.name, time.Time{}, bytes.NewReader(body))
+}
+
+// negotiateEncoding picks the available encoding the client weights
+// highest in its Accept-Encoding header, preferring br over gzip on a tie.
+// It returns "" when the identity body should be sent.
+func negotiateEncoding(accept string, available map[string][]byte) string {
+	weights := make(map[string]float64)
+	for _, part := range strings.Split(accept, ",") {
+		coding, params, _ := strings.Cut(strings.TrimSpace(part), ";")
+		coding = strings.ToLower(strings.TrimSpace(coding))
+		if coding == "" {
+			continue
+		}
+		weight := 1.0
+		if value, ok := strings.CutPrefix(strings.TrimSpace(params), "q="); ok {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				continue
+			}
+			weight = parsed
+		}
+		weights[coding] = weight
+	}
+
+	var chosen string
+	var best float64
+	for _, encoding := range precompressedEncodings {
+		if _, ok := available[encoding.name]; !ok {
+			continue
+		}
+		weight, ok := weights[encoding.name]
+		if !ok {
+			weight, ok = weights["*"]
+		}
+		if ok && weight > best {
+			chosen, best = encoding.name, weight
+		}
+	}
+	return chosen
+}
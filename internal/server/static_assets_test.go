@@ -0,0 +1,204 @@
+package server
+
+import (
+	"crypto/sha512"
+	"encoding/base64"
+	"net/http"
+	"net/http/httptest"
+	"regexp"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"bitriver-live/web"
+)
+
+func testStaticAssets(t *testing.T) *staticAssets {
+	t.Helper()
+	assets, err := loadStaticAssets(fstest.MapFS{
+		"index.html":      {Data: []byte(`<link rel="stylesheet" href="/static/styles.css" /><script src="/static/app.js" type="module"></script><a href="/static/missing.txt">x</a>`)},
+		"index.html.gz":   {Data: []byte("stale")},
+		"app.js":          {Data: []byte("console.log('app');")},
+		"app.js.gz":       {Data: []byte("gzip-body")},
+		"app.js.br":       {Data: []byte("br-body")},
+		"styles.css":      {Data: []byte("body { color: red; }")},
+		"images/logo.svg": {Data: []byte("<svg></svg>")},
+	})
+	if err != nil {
+		t.Fatalf("loadStaticAssets: %v", err)
+	}
+	return assets
+}
+
+func serveAsset(assets *staticAssets, path string, headers map[string]string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(http.MethodGet, "/static/"+path, nil)
+	for key, value := range headers {
+		req.Header.Set(key, value)
+	}
+	rec := httptest.NewRecorder()
+	http.StripPrefix("/static/", assets).ServeHTTP(rec, req)
+	return rec
+}
+
+func TestStaticAssetCacheHeaders(t *testing.T) {
+	assets := testStaticAssets(t)
+	manifest := assets.Manifest()
+	fingerprinted := strings.TrimPrefix(manifest["styles.css"].Path, "/static/")
+	if !regexp.MustCompile(`^styles\.[0-9a-f]{12}\.css$`).MatchString(fingerprinted) {
+		t.Fatalf("expected a content hash in the fingerprinted name, got %q", fingerprinted)
+	}
+	if _, ok := manifest["index.html"]; ok {
+		t.Fatal("expected entry points to stay out of the manifest")
+	}
+
+	cases := []struct {
+		path         string
+		cacheControl string
+		contentType  string
+	}{
+		{fingerprinted, immutableCacheControl, "text/css"},
+		{"styles.css", revalidateCacheControl, "text/css"},
+		{strings.TrimPrefix(manifest["images/logo.svg"].Path, "/static/"), immutableCacheControl, "image/svg+xml"},
+		{"index.html", revalidateCacheControl, "text/html"},
+	}
+	for _, tc := range cases {
+		rec := serveAsset(assets, tc.path, nil)
+		if rec.Code != http.StatusOK {
+			t.Fatalf("%s: expected status 200, got %d", tc.path, rec.Code)
+		}
+		if got := rec.Header().Get("Cache-Control"); got != tc.cacheControl {
+			t.Fatalf("%s: expected Cache-Control %q, got %q", tc.path, tc.cacheControl, got)
+		}
+		if got := rec.Header().Get("Content-Type"); !strings.HasPrefix(got, tc.contentType) {
+			t.Fatalf("%s: expected Content-Type %s, got %q", tc.path, tc.contentType, got)
+		}
+		if rec.Header().Get("ETag") == "" {
+			t.Fatalf("%s: expected an ETag", tc.path)
+		}
+	}
+
+	if rec := serveAsset(assets, "styles.000000000000.css", nil); rec.Code != http.StatusNotFound {
+		t.Fatalf("expected an unknown fingerprint to be 404, got %d", rec.Code)
+	}
+	req := httptest.NewRequest(http.MethodPost, "/styles.css", nil)
+	rec := httptest.NewRecorder()
+	assets.ServeHTTP(rec, req)
+	if rec.Code != http.StatusMethodNotAllowed {
+		t.Fatalf("expected POST to be rejected, got %d", rec.Code)
+	}
+}
+
+func TestStaticAssetEncodingNegotiation(t *testing.T) {
+	assets := testStaticAssets(t)
+	cases := []struct {
+		accept   string
+		encoding string
+		body     string
+	}{
+		{"gzip, deflate, br", "br", "br-body"},
+		{"gzip", "gzip", "gzip-body"},
+		{"br;q=0, gzip;q=0.5", "gzip", "gzip-body"},
+		{"br;q=0.4, gzip;q=0.8", "gzip", "gzip-body"},
+		{"*", "br", "br-body"},
+		{"identity", "", "console.log('app');"},
+		{"", "", "console.log('app');"},
+	}
+	for _, tc := range cases {
+		rec := serveAsset(assets, "app.js", map[string]string{"Accept-Encoding": tc.accept})
+		if got := rec.Header().Get("Content-Encoding"); got != tc.encoding {
+			t.Fatalf("Accept-Encoding %q: expected encoding %q, got %q", tc.accept, tc.encoding, got)
+		}
+		if rec.Body.String() != tc.body {
+			t.Fatalf("Accept-Encoding %q: expected body %q, got %q", tc.accept, tc.body, rec.Body.String())
+		}
+		if rec.Header().Get("Vary") != "Accept-Encoding" {
+			t.Fatalf("Accept-Encoding %q: expected Vary: Accept-Encoding, got %q", tc.accept, rec.Header().Get("Vary"))
+		}
+	}
+
+	if rec := serveAsset(assets, "index.html", map[string]string{"Accept-Encoding": "gzip"}); rec.Header().Get("Content-Encoding") != "" || strings.Contains(rec.Body.String(), "stale") {
+		t.Fatal("expected the rewritten entry point to ignore its stale precompressed copy")
+	}
+	if rec := serveAsset(assets, "styles.css", map[string]string{"Accept-Encoding": "br"}); rec.Header().Get("Vary") != "" {
+		t.Fatalf("expected no Vary header without variants, got %q", rec.Header().Get("Vary"))
+	}
+}
+
+func TestStaticAssetNotModified(t *testing.T) {
+	assets := testStaticAssets(t)
+
+	first := serveAsset(assets, "app.js", nil)
+	etag := first.Header().Get("ETag")
+	rec := serveAsset(assets, "app.js", map[string]string{"If-None-Match": etag})
+	if rec.Code != http.StatusNotModified {
+		t.Fatalf("expected 304 for a matching ETag, got %d", rec.Code)
+	}
+	if rec.Body.Len() != 0 {
+		t.Fatalf("expected an empty 304 body, got %q", rec.Body.String())
+	}
+
+	compressed := serveAsset(assets, "app.js", map[string]string{"Accept-Encoding": "br"})
+	if compressed.Header().Get("ETag") == etag {
+		t.Fatal("expected the brotli variant to carry its own ETag")
+	}
+	rec = serveAsset(assets, "app.js", map[string]string{"Accept-Encoding": "br", "If-None-Match": etag})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected the identity ETag not to validate the brotli body, got %d", rec.Code)
+	}
+	rec = serveAsset(assets, "index.html", map[string]string{"If-None-Match": `"stale"`})
+	if rec.Code != http.StatusOK {
+		t.Fatalf("expected a mismatched ETag to return the page, got %d", rec.Code)
+	}
+}
+
+func TestStaticAssetManifestRewritesEntryPoints(t *testing.T) {
+	assets := testStaticAssets(t)
+	index, ok := assets.entryPoint("index.html")
+	if !ok {
+		t.Fatal("expected index.html to be an entry point")
+	}
+	manifest := assets.Manifest()
+	page := string(index)
+	for _, want := range []string{
+		`<link rel="stylesheet" href="` + manifest["styles.css"].Path + `" integrity="` + manifest["styles.css"].Integrity + `" />`,
+		`<script src="` + manifest["app.js"].Path + `" type="module" integrity="` + manifest["app.js"].Integrity + `">`,
+		`<a href="/static/missing.txt">`,
+	} {
+		if !strings.Contains(page, want) {
+			t.Fatalf("expected rewritten page to contain %s, got %s", want, page)
+		}
+	}
+}
+
+func TestEmbeddedControlCentreReferencesResolve(t *testing.T) {
+	staticFS, err := web.Static()
+	if err != nil {
+		t.Fatalf("Static error: %v", err)
+	}
+	assets, err := loadStaticAssets(staticFS)
+	if err != nil {
+		t.Fatalf("loadStaticAssets: %v", err)
+	}
+
+	reference := regexp.MustCompile(`(?:src|href)="(/static/[^"]+)" [^>]*integrity="([^"]+)"`)
+	for _, page := range []string{"index.html", "signup.html"} {
+		body, ok := assets.entryPoint(page)
+		if !ok {
+			t.Fatalf("expected %s to be an entry point", page)
+		}
+		matches := reference.FindAllStringSubmatch(string(body), -1)
+		if len(matches) < 2 {
+			t.Fatalf("%s: expected fingerprinted stylesheet and script references, got %d", page, len(matches))
+		}
+		for _, match := range matches {
+			rec := serveAsset(assets, strings.TrimPrefix(match[1], "/static/"), nil)
+			if rec.Code != http.StatusOK || rec.Header().Get("Cache-Control") != immutableCacheControl {
+				t.Fatalf("%s: expected %s to be served immutable, got %d %q", page, match[1], rec.Code, rec.Header().Get("Cache-Control"))
+			}
+			sum := sha512.Sum384(rec.Body.Bytes())
+			if integrity := "sha384-" + base64.StdEncoding.EncodeToString(sum[:]); integrity != match[2] {
+				t.Fatalf("%s: integrity of %s does not match its content", page, match[1])
+			}
+		}
+	}
+}
@@ -0,0 +1,238 @@
+package server
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"bitriver-live/internal/observability/metrics"
+)
+
+const (
+	defaultTLSReloadInterval = time.Minute
+	adminPathPrefix          = "/api/admin/"
+)
+
+// fileStamp captures the attributes polled to detect that a certificate or key
+// file was replaced on disk.
+type fileStamp struct {
+	modTime time.Time
+	size    int64
+}
+
+// certReloader serves the TLS certificate through tls.Config.GetCertificate
+// and swaps it when the files on disk change. Replacement pairs are validated
+// before they are installed so a bad rotation keeps the current certificate in
+// service.
+type certReloader struct {
+	certFile string
+	keyFile  string
+	interval time.Duration
+	logger   *slog.Logger
+	metrics  *metrics.Recorder
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	certStamp fileStamp
+	keyStamp  fileStamp
+
+	stopOnce sync.Once
+	stop     chan struct{}
+}
+
+func newCertReloader(certFile, keyFile string, interval time.Duration, logger *slog.Logger, recorder *metrics.Recorder) (*certReloader, error) {
+	if interval <= 0 {
+		interval = defaultTLSReloadInterval
+	}
+	r := &certReloader{
+		certFile: filepath.Clean(certFile),
+		keyFile:  filepath.Clean(keyFile),
+		interval: interval,
+		logger:   logger,
+		metrics:  recorder,
+		stop:     make(chan struct{}),
+	}
+	certStamp, keyStamp, err := r.stampFiles()
+	if err != nil {
+		return nil, err
+	}
+	cert, err := loadServingCertificate(r.certFile, r.keyFile, time.Now())
+	if err != nil {
+		return nil, err
+	}
+	r.cert = cert
+	r.certStamp = certStamp
+	r.keyStamp = keyStamp
+	return r, nil
+}
+
+// GetCertificate returns the certificate currently in service.
+func (r *certReloader) GetCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	return r.cert, nil
+}
+
+// run polls the certificate and key files until Stop is called.
+func (r *certReloader) run() {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-r.stop:
+			return
+		case <-ticker.C:
+			r.reloadIfChanged()
+		}
+	}
+}
+
+// Stop ends the polling loop started by run. It is safe to call when run was
+// never started.
+func (r *certReloader) Stop() {
+	r.stopOnce.Do(func() { close(r.stop) })
+}
+
+// reloadIfChanged loads the pair when either file changed since the last
+// attempt. A rejected pair is remembered so the same broken files are not
+// reported on every tick; the next write to either file triggers a retry.
+func (r *certReloader) reloadIfChanged() {
+	certStamp, keyStamp, err := r.stampFiles()
+	if err != nil {
+		r.reject(err)
+		return
+	}
+
+	r.mu.RLock()
+	unchanged := certStamp == r.certStamp && keyStamp == r.keyStamp
+	r.mu.RUnlock()
+	if unchanged {
+		return
+	}
+
+	cert, err := loadServingCertificate(r.certFile, r.keyFile, time.Now())
+	r.mu.Lock()
+	r.certStamp = certStamp
+	r.keyStamp = keyStamp
+	if err == nil {
+		r.cert = cert
+	}
+	r.mu.Unlock()
+	if err != nil {
+		r.reject(err)
+		return
+	}
+
+	if r.metrics != nil {
+		r.metrics.ObserveTLSReload("swapped")
+	}
+	if r.logger != nil {
+		r.logger.Info("tls certificate reloaded", "cert_file", r.certFile, "not_after", cert.Leaf.NotAfter)
+	}
+}
+
+func (r *certReloader) reject(err error) {
+	if r.metrics != nil {
+		r.metrics.ObserveTLSReload("rejected")
+	}
+	if r.logger != nil {
+		r.logger.Error("tls certificate reload rejected; keeping current certificate", "cert_file", r.certFile, "error", err)
+	}
+}
+
+func (r *certReloader) stampFiles() (fileStamp, fileStamp, error) {
+	certInfo, err := os.Stat(r.certFile)
+	if err != nil {
+		return fileStamp{}, fileStamp{}, fmt.Errorf("stat tls certificate: %w", err)
+	}
+	keyInfo, err := os.Stat(r.keyFile)
+	if err != nil {
+		return fileStamp{}, fileStamp{}, fmt.Errorf("stat tls key: %w", err)
+	}
+	return fileStamp{modTime: certInfo.ModTime(), size: certInfo.Size()},
+		fileStamp{modTime: keyInfo.ModTime(), size: keyInfo.Size()}, nil
+}
+
+// loadServingCertificate loads a key pair and checks that the leaf parses and
+// is inside its validity window at now.
+func loadServingCertificate(certFile, keyFile string, now time.Time) (*tls.Certificate, error) {
+	cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("load tls key pair: %w", err)
+	}
+	if len(cert.Certificate) == 0 {
+		return nil, errors.New("tls certificate file contains no certificates")
+	}
+	leaf, err := x509.ParseCertificate(cert.Certificate[0])
+	if err != nil {
+		return nil, fmt.Errorf("parse tls certificate: %w", err)
+	}
+	if now.Before(leaf.NotBefore) {
+		return nil, fmt.Errorf("tls certificate is not valid until %s", leaf.NotBefore.UTC().Format(time.RFC3339))
+	}
+	if now.After(leaf.NotAfter) {
+		return nil, fmt.Errorf("tls certificate expired at %s", leaf.NotAfter.UTC().Format(time.RFC3339))
+	}
+	cert.Leaf = leaf
+	return &cert, nil
+}
+
+// loadClientCAs reads the PEM bundle used to verify admin client certificates.
+func loadClientCAs(path string) (*x509.CertPool, error) {
+	pemData, err := os.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("read tls client ca: %w", err)
+	}
+	pool := x509.NewCertPool()
+	if !pool.AppendCertsFromPEM(pemData) {
+		return nil, errors.New("tls client ca is invalid")
+	}
+	return pool, nil
+}
+
+// newServerTLSConfig builds the listener configuration. Client certificates
+// are requested and verified when offered, but only the admin prefix insists
+// on one (see adminClientCertMiddleware) so clients without a certificate can
+// still reach the viewer and the rest of the API.
+func newServerTLSConfig(reloader *certReloader, clientCAs *x509.CertPool) *tls.Config {
+	cfg := &tls.Config{
+		MinVersion:     tls.VersionTLS12,
+		GetCertificate: reloader.GetCertificate,
+	}
+	if clientCAs != nil {
+		cfg.ClientCAs = clientCAs
+		cfg.ClientAuth = tls.VerifyClientCertIfGiven
+	}
+	return cfg
+}
+
+// adminClientCertMiddleware rejects /api/admin/* requests that did not
+// present a client certificate verified against the configured CA. It is a
+// no-op when required is false.
+func adminClientCertMiddleware(required bool, logger *slog.Logger, resolver *clientIPResolver, next http.Handler) http.Handler {
+	if !required {
+		return next
+	}
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasPrefix(r.URL.Path, adminPathPrefix) {
+			next.ServeHTTP(w, r)
+			return
+		}
+		if r.TLS == nil || len(r.TLS.VerifiedChains) == 0 {
+			if requestLogger := loggingWithRequest(logger, resolver, r); requestLogger != nil {
+				requestLogger.Warn("admin request without verified client certificate")
+			}
+			writeMiddlewareError(w, http.StatusForbidden, "client certificate required")
+			return
+		}
+		next.ServeHTTP(w, r)
+	})
+}
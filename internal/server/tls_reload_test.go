@@ -0,0 +1,342 @@
+package server
+
+import (
+	"bytes"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"io"
+	"log"
+	"math/big"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/observability/metrics"
+)
+
+type testCertificate struct {
+	cert    *x509.Certificate
+	key     *ecdsa.PrivateKey
+	certPEM []byte
+	keyPEM  []byte
+}
+
+func newTestCertificate(t *testing.T, commonName string, parent *testCertificate, isCA bool) *testCertificate {
+	t.Helper()
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		t.Fatalf("generate key: %v", err)
+	}
+	serial, err := rand.Int(rand.Reader, big.NewInt(1<<62))
+	if err != nil {
+		t.Fatalf("serial: %v", err)
+	}
+	template := &x509.Certificate{
+		SerialNumber:          serial,
+		Subject:               pkix.Name{CommonName: commonName},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageCertSign,
+		ExtKeyUsage:           []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+		IPAddresses:           []net.IP{net.ParseIP("127.0.0.1")},
+		DNSNames:              []string{"localhost"},
+	}
+	signer, signerKey := template, key
+	if parent != nil {
+		signer, signerKey = parent.cert, parent.key
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, signer, &key.PublicKey, signerKey)
+	if err != nil {
+		t.Fatalf("create certificate: %v", err)
+	}
+	cert, err := x509.ParseCertificate(der)
+	if err != nil {
+		t.Fatalf("parse certificate: %v", err)
+	}
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		t.Fatalf("marshal key: %v", err)
+	}
+	return &testCertificate{
+		cert:    cert,
+		key:     key,
+		certPEM: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		keyPEM:  pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}),
+	}
+}
+
+func (c *testCertificate) pool() *x509.CertPool {
+	pool := x509.NewCertPool()
+	pool.AddCert(c.cert)
+	return pool
+}
+
+func (c *testCertificate) tlsCertificate(t *testing.T) tls.Certificate {
+	t.Helper()
+	pair, err := tls.X509KeyPair(c.certPEM, c.keyPEM)
+	if err != nil {
+		t.Fatalf("key pair: %v", err)
+	}
+	return pair
+}
+
+// writeTestFile writes data and moves the mtime forward so the poller sees
+// the change even on filesystems with coarse timestamps.
+func writeTestFile(t *testing.T, path string, data []byte, modTime time.Time) {
+	t.Helper()
+	if err := os.WriteFile(path, data, 0o600); err != nil {
+		t.Fatalf("write %s: %v", path, err)
+	}
+	if err := os.Chtimes(path, modTime, modTime); err != nil {
+		t.Fatalf("chtimes %s: %v", path, err)
+	}
+}
+
+func serveTLS(t *testing.T, handler http.Handler, cfg *tls.Config) string {
+	t.Helper()
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("listen: %v", err)
+	}
+	srv := &http.Server{Handler: handler, TLSConfig: cfg, ErrorLog: log.New(io.Discard, "", 0)}
+	go func() { _ = srv.Serve(tls.NewListener(ln, cfg)) }()
+	t.Cleanup(func() { _ = srv.Close() })
+	return "https://" + ln.Addr().String()
+}
+
+func tlsClient(roots *x509.CertPool, clientCerts ...tls.Certificate) *http.Client {
+	return &http.Client{
+		Timeout: 5 * time.Second,
+		Transport: &http.Transport{
+			DisableKeepAlives: true,
+			TLSClientConfig: &tls.Config{
+				RootCAs:      roots,
+				Certificates: clientCerts,
+			},
+		},
+	}
+}
+
+func TestCertReloaderSwapsWithoutDroppingInFlightRequests(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "tls.crt")
+	keyPath := filepath.Join(dir, "tls.key")
+	original := newTestCertificate(t, "original", nil, true)
+	base := time.Now().Add(-time.Minute)
+	writeTestFile(t, certPath, original.certPEM, base)
+	writeTestFile(t, keyPath, original.keyPEM, base)
+
+	recorder := metrics.New()
+	reloader, err := newCertReloader(certPath, keyPath, time.Hour, nil, recorder)
+	if err != nil {
+		t.Fatalf("newCertReloader: %v", err)
+	}
+
+	entered := make(chan struct{})
+	release := make(chan struct{})
+	handler := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/slow" {
+			close(entered)
+			<-release
+		}
+		_, _ = io.WriteString(w, "ok")
+	})
+	baseURL := serveTLS(t, handler, newServerTLSConfig(reloader, nil))
+
+	type result struct {
+		body string
+		err  error
+	}
+	inFlight := make(chan result, 1)
+	go func() {
+		resp, err := tlsClient(original.pool()).Get(baseURL + "/slow")
+		if err != nil {
+			inFlight <- result{err: err}
+			return
+		}
+		defer resp.Body.Close()
+		body, err := io.ReadAll(resp.Body)
+		inFlight <- result{body: string(body), err: err}
+	}()
+	select {
+	case <-entered:
+	case <-time.After(5 * time.Second):
+		t.Fatal("slow request never reached the handler")
+	}
+
+	rotated := newTestCertificate(t, "rotated", nil, true)
+	writeTestFile(t, certPath, rotated.certPEM, base.Add(30*time.Second))
+	writeTestFile(t, keyPath, rotated.keyPEM, base.Add(30*time.Second))
+	reloader.reloadIfChanged()
+
+	if got := recorder.TLSReloadCounts()["swapped"]; got != 1 {
+		t.Fatalf("expected one swap, got %d", got)
+	}
+
+	resp, err := tlsClient(rotated.pool()).Get(baseURL + "/")
+	if err != nil {
+		t.Fatalf("request with rotated roots: %v", err)
+	}
+	resp.Body.Close()
+	if served := resp.TLS.PeerCertificates[0]; !bytes.Equal(served.Raw, rotated.cert.Raw) {
+		t.Fatalf("expected rotated certificate, got %q", served.Subject.CommonName)
+	}
+	if _, err := tlsClient(original.pool()).Get(baseURL + "/"); err == nil {
+		t.Fatal("expected new connections to stop serving the original certificate")
+	}
+
+	close(release)
+	select {
+	case res := <-inFlight:
+		if res.err != nil {
+			t.Fatalf("in-flight request failed: %v", res.err)
+		}
+		if res.body != "ok" {
+			t.Fatalf("unexpected in-flight body %q", res.body)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("in-flight request did not complete")
+	}
+}
+
+func TestCertReloaderRejectsInvalidReplacement(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "tls.crt")
+	keyPath := filepath.Join(dir, "tls.key")
+	original := newTestCertificate(t, "original", nil, true)
+	base := time.Now().Add(-time.Minute)
+	writeTestFile(t, certPath, original.certPEM, base)
+	writeTestFile(t, keyPath, original.keyPEM, base)
+
+	recorder := metrics.New()
+	reloader, err := newCertReloader(certPath, keyPath, time.Hour, nil, recorder)
+	if err != nil {
+		t.Fatalf("newCertReloader: %v", err)
+	}
+	served, _ := reloader.GetCertificate(nil)
+
+	// A certificate whose key was not rotated alongside it.
+	mismatched := newTestCertificate(t, "mismatched", nil, true)
+	writeTestFile(t, certPath, mismatched.certPEM, base.Add(30*time.Second))
+	reloader.reloadIfChanged()
+	// Polling again without further changes must not report the same failure.
+	reloader.reloadIfChanged()
+
+	writeTestFile(t, certPath, []byte("not a certificate"), base.Add(45*time.Second))
+	reloader.reloadIfChanged()
+
+	if got := recorder.TLSReloadCounts()["rejected"]; got != 2 {
+		t.Fatalf("expected two rejected reloads, got %d", got)
+	}
+	if got := recorder.TLSReloadCounts()["swapped"]; got != 0 {
+		t.Fatalf("expected no swaps, got %d", got)
+	}
+	current, _ := reloader.GetCertificate(nil)
+	if current != served {
+		t.Fatal("expected the original certificate to stay in service")
+	}
+
+	baseURL := serveTLS(t, http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}), newServerTLSConfig(reloader, nil))
+	resp, err := tlsClient(original.pool()).Get(baseURL + "/")
+	if err != nil {
+		t.Fatalf("request after rejected reload: %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestNewRejectsInvalidTLSFiles(t *testing.T) {
+	dir := t.TempDir()
+	certPath := filepath.Join(dir, "tls.crt")
+	keyPath := filepath.Join(dir, "tls.key")
+	writeTestFile(t, certPath, []byte("garbage"), time.Now())
+	writeTestFile(t, keyPath, []byte("garbage"), time.Now())
+
+	handler, _ := newTestHandler(t)
+	if _, err := New(handler, Config{TLS: TLSConfig{CertFile: certPath, KeyFile: keyPath}}); err == nil {
+		t.Fatal("expected invalid certificate files to fail New")
+	}
+
+	handler, _ = newTestHandler(t)
+	if _, err := New(handler, Config{TLS: TLSConfig{ClientCAFile: certPath}}); err == nil {
+		t.Fatal("expected a client CA without a certificate to fail New")
+	}
+}
+
+func TestAdminRoutesRequireClientCertificate(t *testing.T) {
+	dir := t.TempDir()
+	serverCert := newTestCertificate(t, "server", nil, true)
+	clientCA := newTestCertificate(t, "admin-ca", nil, true)
+	adminClient := newTestCertificate(t, "operator", clientCA, false)
+	strangerCA := newTestCertificate(t, "stranger-ca", nil, true)
+	stranger := newTestCertificate(t, "stranger", strangerCA, false)
+
+	certPath := filepath.Join(dir, "tls.crt")
+	keyPath := filepath.Join(dir, "tls.key")
+	caPath := filepath.Join(dir, "clients.pem")
+	writeTestFile(t, certPath, serverCert.certPEM, time.Now())
+	writeTestFile(t, keyPath, serverCert.keyPEM, time.Now())
+	writeTestFile(t, caPath, clientCA.certPEM, time.Now())
+
+	handler, _ := newTestHandler(t)
+	srv, err := New(handler, Config{
+		Metrics: metrics.New(),
+		TLS:     TLSConfig{CertFile: certPath, KeyFile: keyPath, ClientCAFile: caPath},
+	})
+	if err != nil {
+		t.Fatalf("New error: %v", err)
+	}
+	t.Cleanup(srv.tlsReloader.Stop)
+	baseURL := serveTLS(t, srv.httpServer.Handler, srv.httpServer.TLSConfig)
+
+	anonymous := tlsClient(serverCert.pool())
+	resp, err := anonymous.Get(baseURL + "/api/admin/health/components")
+	if err != nil {
+		t.Fatalf("admin request without client cert: %v", err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusForbidden {
+		t.Fatalf("expected 403 without client cert, got %d", resp.StatusCode)
+	}
+	if msg := decodeAPIError(t, body).Error.Message; msg != "client certificate required" {
+		t.Fatalf("unexpected error message %q", msg)
+	}
+
+	resp, err = anonymous.Get(baseURL + "/healthz")
+	if err != nil {
+		t.Fatalf("health request without client cert: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected non-admin routes to skip client certs, got %d", resp.StatusCode)
+	}
+
+	// Clients only offer certificates the server's CA list accepts, so a cert
+	// from another CA either fails the handshake or arrives without one.
+	resp, err = tlsClient(serverCert.pool(), stranger.tlsCertificate(t)).Get(baseURL + "/api/admin/health/components")
+	if err == nil {
+		resp.Body.Close()
+		if resp.StatusCode != http.StatusForbidden {
+			t.Fatalf("expected 403 for a client cert from an unknown CA, got %d", resp.StatusCode)
+		}
+	}
+
+	resp, err = tlsClient(serverCert.pool(), adminClient.tlsCertificate(t)).Get(baseURL + "/api/admin/health/components")
+	if err != nil {
+		t.Fatalf("admin request with client cert: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode == http.StatusForbidden {
+		t.Fatalf("expected verified client cert to pass the admin gate, got %d", resp.StatusCode)
+	}
+}
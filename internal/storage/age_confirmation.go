@@ -0,0 +1,60 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"time"
+
+	"bitriver-live/internal/models"
+)
+
+// ErrBirthDateAlreadySet is returned when a user tries to change a birth date
+// they already confirmed.
+var ErrBirthDateAlreadySet = precondition("birth date has already been set")
+
+// earliestBirthDate bounds birth dates to reject obvious typos.
+var earliestBirthDate = time.Date(1900, time.January, 1, 0, 0, 0, 0, time.UTC)
+
+// normalizeBirthDate truncates a birth date to its calendar day in UTC and
+// rejects dates in the future or implausibly far in the past.
+func normalizeBirthDate(birthDate, now time.Time) (time.Time, error) {
+	date := time.Date(birthDate.Year(), birthDate.Month(), birthDate.Day(), 0, 0, 0, 0, time.UTC)
+	if date.After(now.UTC()) {
+		return time.Time{}, errors.New("birth date cannot be in the future")
+	}
+	if date.Before(earliestBirthDate) {
+		return time.Time{}, fmt.Errorf("birth date must be after %s", earliestBirthDate.Format(time.DateOnly))
+	}
+	return date, nil
+}
+
+// ConfirmUserBirthDate records the user's birth date and the time they
+// confirmed it. The birth date can only be set once.
+func (s *Storage) ConfirmUserBirthDate(id string, birthDate time.Time) (models.User, error) {
+	now := time.Now().UTC()
+	date, err := normalizeBirthDate(birthDate, now)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	updatedData := cloneDataset(s.data)
+	user, ok := updatedData.Users[id]
+	if !ok {
+		return models.User{}, notFound("user", id)
+	}
+	if user.BirthDate != nil {
+		return models.User{}, ErrBirthDateAlreadySet
+	}
+	user.BirthDate = &date
+	user.AgeConfirmedAt = &now
+	updatedData.Users[id] = user
+
+	if err := s.persistDataset(updatedData); err != nil {
+		return models.User{}, err
+	}
+	s.data = updatedData
+	return user, nil
+}
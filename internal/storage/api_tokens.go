@@ -0,0 +1,232 @@
+package storage
+
+import (
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"bitriver-live/internal/models"
+)
+
+var validAPITokenScopes = map[string]struct{}{
+	APITokenScopeRead:          {},
+	APITokenScopeChat:          {},
+	APITokenScopeManageChannel: {},
+}
+
+// CreateAPIToken issues a personal access token for the user and returns the
+// stored record alongside the plaintext secret. The secret is never persisted.
+func (s *Storage) CreateAPIToken(params CreateAPITokenParams) (models.APIToken, string, error) {
+	token, err := newAPITokenRecord(params, time.Now().UTC())
+	if err != nil {
+		return models.APIToken{}, "", err
+	}
+	secret, hashed, err := generateAPITokenSecret()
+	if err != nil {
+		return models.APIToken{}, "", err
+	}
+	token.TokenHash = hashed
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.data.Users[token.UserID]; !ok {
+		return models.APIToken{}, "", notFound("user", token.UserID)
+	}
+
+	id, err := generateID()
+	if err != nil {
+		return models.APIToken{}, "", err
+	}
+	token.ID = id
+
+	updatedData := cloneDataset(s.data)
+	if updatedData.APITokens == nil {
+		updatedData.APITokens = make(map[string]models.APIToken)
+	}
+	updatedData.APITokens[id] = token
+
+	if err := s.persistDataset(updatedData); err != nil {
+		return models.APIToken{}, "", err
+	}
+
+	s.data = updatedData
+
+	return cloneAPIToken(token), secret, nil
+}
+
+// ListAPITokens returns the user's tokens ordered by creation time, newest first.
+func (s *Storage) ListAPITokens(userID string) ([]models.APIToken, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.data.Users[userID]; !ok {
+		return nil, notFound("user", userID)
+	}
+
+	tokens := make([]models.APIToken, 0)
+	for _, token := range s.data.APITokens {
+		if token.UserID == userID {
+			tokens = append(tokens, cloneAPIToken(token))
+		}
+	}
+	sortAPITokens(tokens)
+	return tokens, nil
+}
+
+// RevokeAPIToken deletes the token when it belongs to the user.
+func (s *Storage) RevokeAPIToken(userID, tokenID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	token, ok := s.data.APITokens[tokenID]
+	if !ok || token.UserID != userID {
+		return ErrAPITokenNotFound
+	}
+
+	updatedData := cloneDataset(s.data)
+	delete(updatedData.APITokens, tokenID)
+
+	if err := s.persistDataset(updatedData); err != nil {
+		return err
+	}
+
+	s.data = updatedData
+
+	return nil
+}
+
+// AuthenticateAPIToken resolves a plaintext secret to its token and owner.
+func (s *Storage) AuthenticateAPIToken(secret string) (models.APIToken, models.User, error) {
+	hashed, err := hashAPITokenSecret(secret)
+	if err != nil {
+		return models.APIToken{}, models.User{}, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	now := time.Now().UTC()
+	for _, token := range s.data.APITokens {
+		if token.TokenHash != hashed {
+			continue
+		}
+		if apiTokenExpired(token, now) {
+			return models.APIToken{}, models.User{}, ErrInvalidAPIToken
+		}
+		user, ok := s.data.Users[token.UserID]
+		if !ok || user.Deactivated() {
+			return models.APIToken{}, models.User{}, ErrInvalidAPIToken
+		}
+		return cloneAPIToken(token), user, nil
+	}
+	return models.APIToken{}, models.User{}, ErrInvalidAPIToken
+}
+
+// newAPITokenRecord validates the creation parameters and returns a token
+// populated with everything except its identifier and hash.
+func newAPITokenRecord(params CreateAPITokenParams, now time.Time) (models.APIToken, error) {
+	userID := strings.TrimSpace(params.UserID)
+	if userID == "" {
+		return models.APIToken{}, errors.New("user id is required")
+	}
+	name := strings.TrimSpace(params.Name)
+	if name == "" {
+		return models.APIToken{}, errors.New("token name is required")
+	}
+	if utf8.RuneCountInString(name) > MaxAPITokenNameLength {
+		return models.APIToken{}, fmt.Errorf("token name exceeds %d characters", MaxAPITokenNameLength)
+	}
+	scopes, err := normalizeAPITokenScopes(params.Scopes)
+	if err != nil {
+		return models.APIToken{}, err
+	}
+	token := models.APIToken{
+		UserID:    userID,
+		Name:      name,
+		Scopes:    scopes,
+		CreatedAt: now,
+	}
+	if params.ExpiresAt != nil {
+		expires := params.ExpiresAt.UTC()
+		if !expires.After(now) {
+			return models.APIToken{}, errors.New("token expiry must be in the future")
+		}
+		token.ExpiresAt = &expires
+	}
+	return token, nil
+}
+
+func normalizeAPITokenScopes(scopes []string) ([]string, error) {
+	if len(scopes) == 0 {
+		return []string{APITokenScopeRead}, nil
+	}
+	seen := make(map[string]struct{}, len(scopes))
+	normalized := make([]string, 0, len(scopes))
+	for _, scope := range scopes {
+		scope = strings.ToLower(strings.TrimSpace(scope))
+		if _, ok := validAPITokenScopes[scope]; !ok {
+			return nil, fmt.Errorf("unsupported token scope %q", scope)
+		}
+		if _, ok := seen[scope]; ok {
+			continue
+		}
+		seen[scope] = struct{}{}
+		normalized = append(normalized, scope)
+	}
+	sort.Strings(normalized)
+	return normalized, nil
+}
+
+func generateAPITokenSecret() (string, string, error) {
+	bytes := make([]byte, 32)
+	if _, err := rand.Read(bytes); err != nil {
+		return "", "", fmt.Errorf("generate access token: %w", err)
+	}
+	secret := APITokenPrefix + hex.EncodeToString(bytes)
+	hashed, err := hashAPITokenSecret(secret)
+	if err != nil {
+		return "", "", err
+	}
+	return secret, hashed, nil
+}
+
+func hashAPITokenSecret(secret string) (string, error) {
+	secret = strings.TrimSpace(secret)
+	if !strings.HasPrefix(secret, APITokenPrefix) || len(secret) == len(APITokenPrefix) {
+		return "", ErrInvalidAPIToken
+	}
+	digest := sha256.Sum256([]byte(secret))
+	return hex.EncodeToString(digest[:]), nil
+}
+
+func apiTokenExpired(token models.APIToken, now time.Time) bool {
+	return token.ExpiresAt != nil && !token.ExpiresAt.After(now)
+}
+
+func sortAPITokens(tokens []models.APIToken) {
+	sort.SliceStable(tokens, func(i, j int) bool {
+		if tokens[i].CreatedAt.Equal(tokens[j].CreatedAt) {
+			return tokens[i].ID < tokens[j].ID
+		}
+		return tokens[i].CreatedAt.After(tokens[j].CreatedAt)
+	})
+}
+
+func cloneAPIToken(token models.APIToken) models.APIToken {
+	cloned := token
+	if token.Scopes != nil {
+		cloned.Scopes = append([]string(nil), token.Scopes...)
+	}
+	if token.ExpiresAt != nil {
+		expires := *token.ExpiresAt
+		cloned.ExpiresAt = &expires
+	}
+	return cloned
+}
@@ -0,0 +1,53 @@
+package storage
+
+import (
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestAPITokensLifecycle(t *testing.T) {
+	RunRepositoryAPITokensLifecycle(t, jsonRepositoryFactory)
+}
+
+func TestNotificationPreferences(t *testing.T) {
+	RunRepositoryNotificationPreferences(t, jsonRepositoryFactory)
+}
+
+func TestAPITokenSecretsHashedAtRest(t *testing.T) {
+	store := newTestStore(t)
+	user, err := store.CreateUser(CreateUserParams{DisplayName: "Bot Owner", Email: "bots@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser error: %v", err)
+	}
+	token, secret, err := store.CreateAPIToken(CreateAPITokenParams{UserID: user.ID, Name: "deck"})
+	if err != nil {
+		t.Fatalf("CreateAPIToken error: %v", err)
+	}
+	if len(token.Scopes) != 1 || token.Scopes[0] != APITokenScopeRead {
+		t.Fatalf("expected default read scope, got %v", token.Scopes)
+	}
+
+	data, err := os.ReadFile(store.filePath)
+	if err != nil {
+		t.Fatalf("read store: %v", err)
+	}
+	if strings.Contains(string(data), secret) {
+		t.Fatal("expected plaintext secret to be absent from persisted store")
+	}
+	expectedHash, err := hashAPITokenSecret(secret)
+	if err != nil {
+		t.Fatalf("hash secret: %v", err)
+	}
+	if !strings.Contains(string(data), expectedHash) {
+		t.Fatal("expected token hash to be persisted")
+	}
+
+	reloaded, err := NewStorage(store.filePath)
+	if err != nil {
+		t.Fatalf("reload store: %v", err)
+	}
+	if _, resolved, err := reloaded.AuthenticateAPIToken(secret); err != nil || resolved.ID != user.ID {
+		t.Fatalf("expected reloaded store to authenticate token, got user %q err %v", resolved.ID, err)
+	}
+}
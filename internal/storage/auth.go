@@ -9,6 +9,7 @@ import (
 	"fmt"
 	"strconv"
 	"strings"
+	"time"
 
 	"bitriver-live/internal/models"
 	"golang.org/x/crypto/pbkdf2"
@@ -32,9 +33,25 @@ func (s *Storage) AuthenticateUser(email, password string) (models.User, error)
 		}
 		return models.User{}, err
 	}
+	if user.Deactivated() {
+		return models.User{}, ErrUserDeactivated
+	}
 	return user, nil
 }
 
+// applyDeactivation returns the deactivation time after setting an account's
+// deactivated state at now. Deactivating an already deactivated account
+// keeps the original time.
+func applyDeactivation(current *time.Time, deactivated bool, now time.Time) *time.Time {
+	if !deactivated {
+		return nil
+	}
+	if current != nil {
+		return current
+	}
+	return &now
+}
+
 // SetUserPassword replaces the stored password hash for the provided user.
 func (s *Storage) SetUserPassword(id, password string) (models.User, error) {
 	if len(password) < 8 {
@@ -53,7 +70,7 @@ func (s *Storage) SetUserPassword(id, password string) (models.User, error) {
 
 	user, ok := updatedData.Users[id]
 	if !ok {
-		return models.User{}, fmt.Errorf("user %s not found", id)
+		return models.User{}, notFound("user", id)
 	}
 
 	user.PasswordHash = hashed
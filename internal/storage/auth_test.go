@@ -23,7 +23,10 @@ func TestCreateAndListUser(t *testing.T) {
 		t.Fatal("expected user ID to be set")
 	}
 
-	users := store.ListUsers()
+	users, err := store.ListUsers()
+	if err != nil {
+		t.Fatalf("ListUsers: %v", err)
+	}
 	if len(users) != 1 {
 		t.Fatalf("expected 1 user, got %d", len(users))
 	}
@@ -0,0 +1,323 @@
+package storage
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"bitriver-live/internal/chat"
+	"bitriver-live/internal/models"
+)
+
+const (
+	// MaxBadgeSlugLength is the longest badge slug, in bytes.
+	MaxBadgeSlugLength = 32
+	// MaxBadgeLabelLength is the longest badge label, in characters.
+	MaxBadgeLabelLength = 32
+	// MaxBadgeIconURLLength is the longest badge icon URL, in bytes.
+	MaxBadgeIconURLLength = 512
+)
+
+var badgeSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// reservedBadgeSlugs are the badges chat derives from a user's relation to
+// the channel. A global badge reusing one would be indistinguishable.
+var reservedBadgeSlugs = map[string]struct{}{
+	chat.BadgeBroadcaster: {},
+	chat.BadgeAdmin:       {},
+	chat.BadgeModerator:   {},
+	chat.BadgeSubscriber:  {},
+}
+
+// defaultBadgeDefinitions are the badges a new datastore starts with.
+// Administrators may relabel or delete them like any other definition.
+func defaultBadgeDefinitions(now time.Time) map[string]models.BadgeDefinition {
+	defaults := map[string]string{
+		"staff":    "Staff",
+		"verified": "Verified",
+		"founder":  "Founder",
+	}
+	definitions := make(map[string]models.BadgeDefinition, len(defaults))
+	for slug, label := range defaults {
+		definitions[slug] = models.BadgeDefinition{Slug: slug, Label: label, CreatedAt: now, UpdatedAt: now}
+	}
+	return definitions
+}
+
+func normalizeBadgeSlug(slug string) (string, error) {
+	slug = strings.ToLower(strings.TrimSpace(slug))
+	if slug == "" {
+		return "", fmt.Errorf("slug is required")
+	}
+	if len(slug) > MaxBadgeSlugLength {
+		return "", fmt.Errorf("slug must be %d characters or fewer", MaxBadgeSlugLength)
+	}
+	if !badgeSlugPattern.MatchString(slug) {
+		return "", fmt.Errorf("slug may only contain lowercase letters, digits, and single hyphens")
+	}
+	if _, reserved := reservedBadgeSlugs[slug]; reserved {
+		return "", fmt.Errorf("slug %q is reserved for channel badges", slug)
+	}
+	return slug, nil
+}
+
+func normalizeBadgeLabel(label string) (string, error) {
+	label = strings.TrimSpace(label)
+	if label == "" {
+		return "", fmt.Errorf("label is required")
+	}
+	if utf8.RuneCountInString(label) > MaxBadgeLabelLength {
+		return "", fmt.Errorf("label must be %d characters or fewer", MaxBadgeLabelLength)
+	}
+	return label, nil
+}
+
+// normalizeBadgeIconURL accepts an absolute http or https URL. Empty values
+// leave the badge without an icon.
+func normalizeBadgeIconURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", nil
+	}
+	if len(raw) > MaxBadgeIconURLLength {
+		return "", fmt.Errorf("iconUrl must be %d bytes or fewer", MaxBadgeIconURLLength)
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return "", fmt.Errorf("iconUrl must be an absolute http or https URL")
+	}
+	return parsed.String(), nil
+}
+
+// newBadgeDefinition validates params and builds the definition row.
+func newBadgeDefinition(params BadgeDefinitionParams, now time.Time) (models.BadgeDefinition, error) {
+	slug, err := normalizeBadgeSlug(params.Slug)
+	if err != nil {
+		return models.BadgeDefinition{}, err
+	}
+	label, err := normalizeBadgeLabel(params.Label)
+	if err != nil {
+		return models.BadgeDefinition{}, err
+	}
+	icon, err := normalizeBadgeIconURL(params.IconURL)
+	if err != nil {
+		return models.BadgeDefinition{}, err
+	}
+	return models.BadgeDefinition{Slug: slug, Label: label, IconURL: icon, CreatedAt: now, UpdatedAt: now}, nil
+}
+
+// applyBadgeDefinitionUpdate applies update to definition.
+func applyBadgeDefinitionUpdate(definition models.BadgeDefinition, update BadgeDefinitionUpdate, now time.Time) (models.BadgeDefinition, error) {
+	if update.Label != nil {
+		label, err := normalizeBadgeLabel(*update.Label)
+		if err != nil {
+			return models.BadgeDefinition{}, err
+		}
+		definition.Label = label
+	}
+	if update.IconURL != nil {
+		icon, err := normalizeBadgeIconURL(*update.IconURL)
+		if err != nil {
+			return models.BadgeDefinition{}, err
+		}
+		definition.IconURL = icon
+	}
+	definition.UpdatedAt = now
+	return definition, nil
+}
+
+// sortBadgeDefinitions orders definitions by creation, then slug.
+func sortBadgeDefinitions(definitions []models.BadgeDefinition) {
+	sort.Slice(definitions, func(i, j int) bool {
+		if definitions[i].CreatedAt.Equal(definitions[j].CreatedAt) {
+			return definitions[i].Slug < definitions[j].Slug
+		}
+		return definitions[i].CreatedAt.Before(definitions[j].CreatedAt)
+	})
+}
+
+// sortBadgeGrants orders grants oldest first, so a user's badges keep a
+// stable order in chat.
+func sortBadgeGrants(grants []models.BadgeGrant) {
+	sort.Slice(grants, func(i, j int) bool {
+		if grants[i].GrantedAt.Equal(grants[j].GrantedAt) {
+			if grants[i].Slug == grants[j].Slug {
+				return grants[i].UserID < grants[j].UserID
+			}
+			return grants[i].Slug < grants[j].Slug
+		}
+		return grants[i].GrantedAt.Before(grants[j].GrantedAt)
+	})
+}
+
+// userBadgesLocked returns the user's grants oldest first. The caller must
+// hold s.mu.
+func (s *Storage) userBadgesLocked(userID string) []models.BadgeGrant {
+	grants := make([]models.BadgeGrant, 0, len(s.data.BadgeGrants[userID]))
+	for _, grant := range s.data.BadgeGrants[userID] {
+		grants = append(grants, grant)
+	}
+	sortBadgeGrants(grants)
+	return grants
+}
+
+// ListBadgeDefinitions returns every badge definition in creation order.
+func (s *Storage) ListBadgeDefinitions() ([]models.BadgeDefinition, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	definitions := make([]models.BadgeDefinition, 0, len(s.data.BadgeDefinitions))
+	for _, definition := range s.data.BadgeDefinitions {
+		definitions = append(definitions, definition)
+	}
+	sortBadgeDefinitions(definitions)
+	return definitions, nil
+}
+
+// CreateBadgeDefinition adds a global badge. It returns ErrBadgeExists when
+// the slug is taken.
+func (s *Storage) CreateBadgeDefinition(params BadgeDefinitionParams) (models.BadgeDefinition, error) {
+	definition, err := newBadgeDefinition(params, time.Now().UTC())
+	if err != nil {
+		return models.BadgeDefinition{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.data.BadgeDefinitions[definition.Slug]; exists {
+		return models.BadgeDefinition{}, ErrBadgeExists
+	}
+	s.data.BadgeDefinitions[definition.Slug] = definition
+	if err := s.persist(); err != nil {
+		delete(s.data.BadgeDefinitions, definition.Slug)
+		return models.BadgeDefinition{}, err
+	}
+	return definition, nil
+}
+
+// UpdateBadgeDefinition changes a badge's label or icon.
+func (s *Storage) UpdateBadgeDefinition(slug string, update BadgeDefinitionUpdate) (models.BadgeDefinition, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, ok := s.data.BadgeDefinitions[slug]
+	if !ok {
+		return models.BadgeDefinition{}, ErrBadgeNotFound
+	}
+	updated, err := applyBadgeDefinitionUpdate(current, update, time.Now().UTC())
+	if err != nil {
+		return models.BadgeDefinition{}, err
+	}
+	s.data.BadgeDefinitions[slug] = updated
+	if err := s.persist(); err != nil {
+		s.data.BadgeDefinitions[slug] = current
+		return models.BadgeDefinition{}, err
+	}
+	return updated, nil
+}
+
+// DeleteBadgeDefinition removes a badge. It returns ErrBadgeInUse while any
+// user still holds it, so deleting a definition never silently strips
+// badges.
+func (s *Storage) DeleteBadgeDefinition(slug string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	definition, ok := s.data.BadgeDefinitions[slug]
+	if !ok {
+		return ErrBadgeNotFound
+	}
+	for _, grants := range s.data.BadgeGrants {
+		if _, held := grants[slug]; held {
+			return ErrBadgeInUse
+		}
+	}
+	delete(s.data.BadgeDefinitions, slug)
+	if err := s.persist(); err != nil {
+		s.data.BadgeDefinitions[slug] = definition
+		return err
+	}
+	return nil
+}
+
+// GrantBadge gives the user a global badge. Granting a badge the user
+// already holds returns the existing grant. It returns ErrBadgeNotFound when
+// no definition has the slug.
+func (s *Storage) GrantBadge(userID, slug, grantedBy string) (models.BadgeGrant, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data.Users[userID]; !ok {
+		return models.BadgeGrant{}, notFound("user", userID)
+	}
+	if _, ok := s.data.BadgeDefinitions[slug]; !ok {
+		return models.BadgeGrant{}, ErrBadgeNotFound
+	}
+	if grant, held := s.data.BadgeGrants[userID][slug]; held {
+		return grant, nil
+	}
+	grant := models.BadgeGrant{UserID: userID, Slug: slug, GrantedBy: grantedBy, GrantedAt: time.Now().UTC()}
+	grants := s.data.BadgeGrants[userID]
+	if grants == nil {
+		grants = make(map[string]models.BadgeGrant)
+		s.data.BadgeGrants[userID] = grants
+	}
+	grants[slug] = grant
+	if err := s.persist(); err != nil {
+		delete(grants, slug)
+		if len(grants) == 0 {
+			delete(s.data.BadgeGrants, userID)
+		}
+		return models.BadgeGrant{}, err
+	}
+	return grant, nil
+}
+
+// RevokeBadge takes a global badge from the user. It returns
+// ErrBadgeGrantNotFound when the user does not hold it.
+func (s *Storage) RevokeBadge(userID, slug string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	grants := s.data.BadgeGrants[userID]
+	grant, held := grants[slug]
+	if !held {
+		return ErrBadgeGrantNotFound
+	}
+	delete(grants, slug)
+	if len(grants) == 0 {
+		delete(s.data.BadgeGrants, userID)
+	}
+	if err := s.persist(); err != nil {
+		if s.data.BadgeGrants[userID] == nil {
+			s.data.BadgeGrants[userID] = grants
+		}
+		grants[slug] = grant
+		return err
+	}
+	return nil
+}
+
+// ListUserBadges returns the user's global badges, oldest grant first. An
+// unknown user has none.
+func (s *Storage) ListUserBadges(userID string) ([]models.BadgeGrant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.userBadgesLocked(userID), nil
+}
+
+// ListBadgeGrants returns every holder of the badge, oldest grant first. It
+// returns ErrBadgeNotFound when no definition has the slug.
+func (s *Storage) ListBadgeGrants(slug string) ([]models.BadgeGrant, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.data.BadgeDefinitions[slug]; !ok {
+		return nil, ErrBadgeNotFound
+	}
+	grants := make([]models.BadgeGrant, 0)
+	for _, held := range s.data.BadgeGrants {
+		if grant, ok := held[slug]; ok {
+			grants = append(grants, grant)
+		}
+	}
+	sortBadgeGrants(grants)
+	return grants, nil
+}
@@ -0,0 +1,202 @@
+package storage
+
+import (
+	"sort"
+	"time"
+
+	"bitriver-live/internal/models"
+)
+
+// ErrAnalyticsRangeInvalid reports an analytics export range that is empty,
+// reversed, or longer than a year.
+var ErrAnalyticsRangeInvalid = invalid("range", "analytics range must end after it starts and span at most one year")
+
+// ChannelSessionExportRow is one stream session as listed by
+// ExportChannelSessions. EndedAt is nil while the session is live.
+type ChannelSessionExportRow struct {
+	SessionID      string
+	StartedAt      time.Time
+	EndedAt        *time.Time
+	PeakConcurrent int
+	// Language is the channel's language when the session started, empty
+	// when none was set or the session predates recorded settings.
+	Language string
+}
+
+// ChannelFollowExportRow counts the follows a channel gained on one UTC day.
+type ChannelFollowExportRow struct {
+	Day     time.Time
+	Follows int
+}
+
+// ChannelRevenueExportRow totals a channel's tips and new subscriptions in
+// one currency on one UTC day. Amounts are never converted between
+// currencies.
+type ChannelRevenueExportRow struct {
+	Day                time.Time
+	Currency           string
+	Tips               int
+	TipAmount          models.Money
+	Subscriptions      int
+	SubscriptionAmount models.Money
+}
+
+// ValidateAnalyticsRange checks that [from, to) is non-empty and no longer
+// than a year.
+func ValidateAnalyticsRange(from, to time.Time) error {
+	if from.IsZero() || to.IsZero() || !to.After(from) || to.After(from.AddDate(1, 0, 0)) {
+		return ErrAnalyticsRangeInvalid
+	}
+	return nil
+}
+
+// analyticsDay truncates t to the start of its UTC day.
+func analyticsDay(t time.Time) time.Time {
+	t = t.UTC()
+	return time.Date(t.Year(), t.Month(), t.Day(), 0, 0, 0, 0, time.UTC)
+}
+
+func inAnalyticsRange(t, from, to time.Time) bool {
+	return !t.Before(from) && t.Before(to)
+}
+
+// ExportChannelSessions calls fn for every session of the channel that
+// started in [from, to), oldest first, stopping at the first error fn
+// returns.
+func (s *Storage) ExportChannelSessions(channelID string, from, to time.Time, fn func(ChannelSessionExportRow) error) error {
+	if err := ValidateAnalyticsRange(from, to); err != nil {
+		return err
+	}
+	s.mu.RLock()
+	if _, ok := s.data.Channels[channelID]; !ok {
+		s.mu.RUnlock()
+		return notFound("channel", channelID)
+	}
+	rows := make([]ChannelSessionExportRow, 0)
+	for _, session := range s.data.StreamSessions {
+		if session.ChannelID != channelID || !inAnalyticsRange(session.StartedAt, from, to) {
+			continue
+		}
+		row := ChannelSessionExportRow{
+			SessionID:      session.ID,
+			StartedAt:      session.StartedAt.UTC(),
+			PeakConcurrent: session.PeakConcurrent,
+		}
+		if session.Settings != nil {
+			row.Language = session.Settings.Language
+		}
+		if session.EndedAt != nil {
+			ended := session.EndedAt.UTC()
+			row.EndedAt = &ended
+		}
+		rows = append(rows, row)
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(rows, func(i, j int) bool {
+		if !rows[i].StartedAt.Equal(rows[j].StartedAt) {
+			return rows[i].StartedAt.Before(rows[j].StartedAt)
+		}
+		return rows[i].SessionID < rows[j].SessionID
+	})
+	for _, row := range rows {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportChannelFollows calls fn for every UTC day in [from, to) on which the
+// channel gained followers, oldest first. Follows that were later undone are
+// not counted.
+func (s *Storage) ExportChannelFollows(channelID string, from, to time.Time, fn func(ChannelFollowExportRow) error) error {
+	if err := ValidateAnalyticsRange(from, to); err != nil {
+		return err
+	}
+	s.mu.RLock()
+	if _, ok := s.data.Channels[channelID]; !ok {
+		s.mu.RUnlock()
+		return notFound("channel", channelID)
+	}
+	counts := make(map[time.Time]int)
+	for _, follows := range s.data.Follows {
+		followedAt, ok := follows[channelID]
+		if !ok || !inAnalyticsRange(followedAt, from, to) {
+			continue
+		}
+		counts[analyticsDay(followedAt)]++
+	}
+	s.mu.RUnlock()
+
+	rows := make([]ChannelFollowExportRow, 0, len(counts))
+	for day, count := range counts {
+		rows = append(rows, ChannelFollowExportRow{Day: day, Follows: count})
+	}
+	sort.Slice(rows, func(i, j int) bool { return rows[i].Day.Before(rows[j].Day) })
+	for _, row := range rows {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ExportChannelRevenue calls fn for every UTC day and currency in [from, to)
+// with tips or new subscriptions, ordered by day and then currency.
+// Subscriptions count on the day they started, whatever their status since.
+func (s *Storage) ExportChannelRevenue(channelID string, from, to time.Time, fn func(ChannelRevenueExportRow) error) error {
+	if err := ValidateAnalyticsRange(from, to); err != nil {
+		return err
+	}
+	type revenueKey struct {
+		day      time.Time
+		currency string
+	}
+	s.mu.RLock()
+	if _, ok := s.data.Channels[channelID]; !ok {
+		s.mu.RUnlock()
+		return notFound("channel", channelID)
+	}
+	totals := make(map[revenueKey]ChannelRevenueExportRow)
+	for _, tip := range s.data.Tips {
+		if tip.ChannelID != channelID || !inAnalyticsRange(tip.CreatedAt, from, to) {
+			continue
+		}
+		key := revenueKey{day: analyticsDay(tip.CreatedAt), currency: tip.Currency}
+		row := totals[key]
+		row.Tips++
+		row.TipAmount = row.TipAmount.Add(tip.Amount)
+		totals[key] = row
+	}
+	for _, subscription := range s.data.Subscriptions {
+		if subscription.ChannelID != channelID || !inAnalyticsRange(subscription.StartedAt, from, to) {
+			continue
+		}
+		key := revenueKey{day: analyticsDay(subscription.StartedAt), currency: subscription.Currency}
+		row := totals[key]
+		row.Subscriptions++
+		row.SubscriptionAmount = row.SubscriptionAmount.Add(subscription.Amount)
+		totals[key] = row
+	}
+	s.mu.RUnlock()
+
+	rows := make([]ChannelRevenueExportRow, 0, len(totals))
+	for key, row := range totals {
+		row.Day = key.day
+		row.Currency = key.currency
+		rows = append(rows, row)
+	}
+	sort.Slice(rows, func(i, j int) bool {
+		if !rows[i].Day.Equal(rows[j].Day) {
+			return rows[i].Day.Before(rows[j].Day)
+		}
+		return rows[i].Currency < rows[j].Currency
+	})
+	for _, row := range rows {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,202 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"bitriver-live/internal/models"
+)
+
+// MaxChannelCategoryLength bounds channel categories in characters.
+const MaxChannelCategoryLength = 64
+
+// Channel batch result statuses.
+const (
+	ChannelBatchUpdated = "updated"
+	ChannelBatchSkipped = "skipped"
+	ChannelBatchFailed  = "error"
+)
+
+// ChannelBatchUpdate is a partial update applied to many channels at once.
+// Category replaces each channel's category when set; AddTags and RemoveTags
+// edit each channel's existing tags rather than replacing them.
+type ChannelBatchUpdate struct {
+	Category   *string
+	AddTags    []string
+	RemoveTags []string
+}
+
+// ChannelBatchResult reports what a batch update did to one channel.
+// Channels the update would not change are skipped, and unknown channels
+// fail without affecting the rest of the batch.
+type ChannelBatchResult struct {
+	ChannelID string
+	Status    string
+	Error     string
+}
+
+// ChannelExportRow is one channel as listed by ExportChannels.
+type ChannelExportRow struct {
+	Channel    models.Channel
+	OwnerEmail string
+	Followers  int
+}
+
+// normalizeChannelCategory trims a category and rejects values that are too
+// long or contain control characters.
+func normalizeChannelCategory(value string) (string, error) {
+	category := strings.TrimSpace(value)
+	if utf8.RuneCountInString(category) > MaxChannelCategoryLength {
+		return "", fmt.Errorf("category must be at most %d characters", MaxChannelCategoryLength)
+	}
+	if strings.IndexFunc(category, unicode.IsControl) >= 0 {
+		return "", errors.New("category cannot contain control characters")
+	}
+	return category, nil
+}
+
+// normalized validates the update and normalizes its category and tags.
+func (u ChannelBatchUpdate) normalized() (ChannelBatchUpdate, error) {
+	normalized := ChannelBatchUpdate{
+		AddTags:    normalizeTags(u.AddTags),
+		RemoveTags: normalizeTags(u.RemoveTags),
+	}
+	if u.Category != nil {
+		category, err := normalizeChannelCategory(*u.Category)
+		if err != nil {
+			return ChannelBatchUpdate{}, err
+		}
+		normalized.Category = &category
+	}
+	if normalized.Category == nil && len(normalized.AddTags) == 0 && len(normalized.RemoveTags) == 0 {
+		return ChannelBatchUpdate{}, errors.New("batch update requires a category or tag change")
+	}
+	return normalized, nil
+}
+
+// apply returns the channel's category and tags after the update and whether
+// either changed. The update must already be normalized.
+func (u ChannelBatchUpdate) apply(category string, tags []string) (string, []string, bool) {
+	nextCategory := category
+	if u.Category != nil {
+		nextCategory = *u.Category
+	}
+	removed := make(map[string]struct{}, len(u.RemoveTags))
+	for _, tag := range u.RemoveTags {
+		removed[tag] = struct{}{}
+	}
+	next := make([]string, 0, len(tags)+len(u.AddTags))
+	for _, tag := range append(append([]string{}, tags...), u.AddTags...) {
+		if _, ok := removed[tag]; !ok {
+			next = append(next, tag)
+		}
+	}
+	nextTags := normalizeTags(next)
+
+	current := normalizeTags(tags)
+	changed := nextCategory != category || len(nextTags) != len(current)
+	for i := 0; !changed && i < len(nextTags); i++ {
+		changed = nextTags[i] != current[i]
+	}
+	return nextCategory, nextTags, changed
+}
+
+// uniqueIDs trims ids and drops blanks and repeats, keeping order.
+func uniqueIDs(ids []string) []string {
+	seen := make(map[string]struct{}, len(ids))
+	unique := make([]string, 0, len(ids))
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		if _, ok := seen[id]; ok {
+			continue
+		}
+		seen[id] = struct{}{}
+		unique = append(unique, id)
+	}
+	return unique
+}
+
+// BatchUpdateChannels applies update to every listed channel and persists
+// all changes together.
+func (s *Storage) BatchUpdateChannels(ids []string, update ChannelBatchUpdate) ([]ChannelBatchResult, error) {
+	normalized, err := update.normalized()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	updatedData := cloneDataset(s.data)
+	now := time.Now().UTC()
+	results := make([]ChannelBatchResult, 0, len(ids))
+	modified := false
+	for _, id := range uniqueIDs(ids) {
+		channel, ok := updatedData.Channels[id]
+		if !ok {
+			results = append(results, ChannelBatchResult{ChannelID: id, Status: ChannelBatchFailed, Error: fmt.Sprintf("channel %s not found", id)})
+			continue
+		}
+		category, tags, changed := normalized.apply(channel.Category, channel.Tags)
+		if !changed {
+			results = append(results, ChannelBatchResult{ChannelID: id, Status: ChannelBatchSkipped})
+			continue
+		}
+		channel.Category = category
+		channel.Tags = tags
+		channel.UpdatedAt = now
+		updatedData.Channels[id] = channel
+		modified = true
+		results = append(results, ChannelBatchResult{ChannelID: id, Status: ChannelBatchUpdated})
+	}
+
+	if modified {
+		if err := s.persistDataset(updatedData); err != nil {
+			return nil, err
+		}
+		s.data = updatedData
+	}
+	return results, nil
+}
+
+// ExportChannels calls fn for every channel, oldest first, stopping at the
+// first error fn returns.
+func (s *Storage) ExportChannels(fn func(ChannelExportRow) error) error {
+	s.mu.RLock()
+	rows := make([]ChannelExportRow, 0, len(s.data.Channels))
+	followers := make(map[string]int, len(s.data.Channels))
+	for _, follows := range s.data.Follows {
+		for channelID := range follows {
+			followers[channelID]++
+		}
+	}
+	for _, channel := range s.data.Channels {
+		rows = append(rows, ChannelExportRow{
+			Channel:    channel,
+			OwnerEmail: s.data.Users[channel.OwnerID].Email,
+			Followers:  followers[channel.ID],
+		})
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(rows, func(i, j int) bool {
+		if rows[i].Channel.CreatedAt.Equal(rows[j].Channel.CreatedAt) {
+			return rows[i].Channel.ID < rows[j].Channel.ID
+		}
+		return rows[i].Channel.CreatedAt.Before(rows[j].Channel.CreatedAt)
+	})
+	for _, row := range rows {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
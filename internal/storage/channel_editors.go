@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/authz"
+	"bitriver-live/internal/models"
+)
+
+// GrantChannelEditor records that the user, who must hold the editor role, may
+// manage the channel's recordings and uploads until expiresAt, or
+// indefinitely when expiresAt is nil. Granting an existing editor keeps the
+// original grant and only updates its expiry.
+func (s *Storage) GrantChannelEditor(channelID, userID, grantedBy string, expiresAt *time.Time) (models.ChannelEditor, error) {
+	channelID = strings.TrimSpace(channelID)
+	userID = strings.TrimSpace(userID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.data.Channels[channelID]; !ok {
+		return models.ChannelEditor{}, notFound("channel", channelID)
+	}
+	user, ok := s.data.Users[userID]
+	if !ok {
+		return models.ChannelEditor{}, notFound("user", userID)
+	}
+	if !user.HasRole(authz.RoleEditor) {
+		return models.ChannelEditor{}, fmt.Errorf("user %s does not have the %s role", userID, authz.RoleEditor)
+	}
+	now := s.grantTime()
+	expires, err := normalizeGrantExpiry(now, expiresAt)
+	if err != nil {
+		return models.ChannelEditor{}, err
+	}
+
+	editor := models.ChannelEditor{
+		ChannelID: channelID,
+		UserID:    userID,
+		GrantedBy: strings.TrimSpace(grantedBy),
+		GrantedAt: now,
+		ExpiresAt: expires,
+	}
+	if existing, ok := s.data.ChannelEditors[channelID][userID]; ok && existing.ActiveAt(now) {
+		if sameGrantExpiry(existing.ExpiresAt, expires) {
+			return existing, nil
+		}
+		editor.GrantedBy = existing.GrantedBy
+		editor.GrantedAt = existing.GrantedAt
+	}
+
+	updatedData := cloneDataset(s.data)
+	if updatedData.ChannelEditors == nil {
+		updatedData.ChannelEditors = make(map[string]map[string]models.ChannelEditor)
+	}
+	editors := updatedData.ChannelEditors[channelID]
+	if editors == nil {
+		editors = make(map[string]models.ChannelEditor)
+	}
+	editors[userID] = editor
+	updatedData.ChannelEditors[channelID] = editors
+
+	if err := s.persistDataset(updatedData); err != nil {
+		return models.ChannelEditor{}, err
+	}
+	s.data = updatedData
+	return editor, nil
+}
+
+// RevokeChannelEditor removes the user's editor grant on the channel. The
+// operation is idempotent.
+func (s *Storage) RevokeChannelEditor(channelID, userID string) error {
+	channelID = strings.TrimSpace(channelID)
+	userID = strings.TrimSpace(userID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.data.Channels[channelID]; !ok {
+		return notFound("channel", channelID)
+	}
+	if _, ok := s.data.ChannelEditors[channelID][userID]; !ok {
+		return nil
+	}
+
+	updatedData := cloneDataset(s.data)
+	editors := updatedData.ChannelEditors[channelID]
+	delete(editors, userID)
+	if len(editors) == 0 {
+		delete(updatedData.ChannelEditors, channelID)
+	}
+
+	if err := s.persistDataset(updatedData); err != nil {
+		return err
+	}
+	s.data = updatedData
+	return nil
+}
+
+// ListChannelEditors returns the channel's unexpired editor grants, oldest
+// first.
+func (s *Storage) ListChannelEditors(channelID string) ([]models.ChannelEditor, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.data.Channels[channelID]; !ok {
+		return nil, notFound("channel", channelID)
+	}
+	now := s.grantTime()
+	editors := make([]models.ChannelEditor, 0, len(s.data.ChannelEditors[channelID]))
+	for _, editor := range s.data.ChannelEditors[channelID] {
+		if editor.ActiveAt(now) {
+			editors = append(editors, editor)
+		}
+	}
+	sortChannelEditors(editors)
+	return editors, nil
+}
+
+// IsChannelEditor reports whether the user holds an unexpired editor grant on
+// the channel.
+func (s *Storage) IsChannelEditor(channelID, userID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	editor, ok := s.data.ChannelEditors[channelID][userID]
+	return ok && editor.ActiveAt(s.grantTime())
+}
+
+func sortChannelEditors(editors []models.ChannelEditor) {
+	sort.Slice(editors, func(i, j int) bool {
+		if editors[i].GrantedAt.Equal(editors[j].GrantedAt) {
+			return editors[i].UserID < editors[j].UserID
+		}
+		return editors[i].GrantedAt.Before(editors[j].GrantedAt)
+	})
+}
@@ -0,0 +1,91 @@
+package storage
+
+import (
+	"time"
+
+	"bitriver-live/internal/models"
+)
+
+// ExpiredChannelGrants lists the time-limited grants removed by
+// PurgeExpiredChannelGrants.
+type ExpiredChannelGrants struct {
+	Moderators []models.ChannelModerator
+	Editors    []models.ChannelEditor
+}
+
+func (s *Storage) grantTime() time.Time {
+	if s.grantNow != nil {
+		return s.grantNow()
+	}
+	return time.Now().UTC()
+}
+
+// normalizeGrantExpiry copies expiresAt in UTC, rejecting expiries closer
+// than models.MinChannelGrantDuration to now. A nil expiry never expires.
+func normalizeGrantExpiry(now time.Time, expiresAt *time.Time) (*time.Time, error) {
+	if expiresAt == nil {
+		return nil, nil
+	}
+	if expiresAt.Before(now.Add(models.MinChannelGrantDuration)) {
+		return nil, ErrGrantExpiryTooSoon
+	}
+	expires := expiresAt.UTC()
+	return &expires, nil
+}
+
+func sameGrantExpiry(a, b *time.Time) bool {
+	if a == nil || b == nil {
+		return a == nil && b == nil
+	}
+	return a.Equal(*b)
+}
+
+// PurgeExpiredChannelGrants deletes moderator and editor grants whose expiry
+// has passed and returns them so callers can announce the change.
+func (s *Storage) PurgeExpiredChannelGrants() (ExpiredChannelGrants, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := s.grantTime()
+	var expired ExpiredChannelGrants
+	for _, moderators := range s.data.ChannelModerators {
+		for _, moderator := range moderators {
+			if !moderator.ActiveAt(now) {
+				expired.Moderators = append(expired.Moderators, moderator)
+			}
+		}
+	}
+	for _, editors := range s.data.ChannelEditors {
+		for _, editor := range editors {
+			if !editor.ActiveAt(now) {
+				expired.Editors = append(expired.Editors, editor)
+			}
+		}
+	}
+	if len(expired.Moderators) == 0 && len(expired.Editors) == 0 {
+		return expired, nil
+	}
+
+	updatedData := cloneDataset(s.data)
+	for _, moderator := range expired.Moderators {
+		moderators := updatedData.ChannelModerators[moderator.ChannelID]
+		delete(moderators, moderator.UserID)
+		if len(moderators) == 0 {
+			delete(updatedData.ChannelModerators, moderator.ChannelID)
+		}
+	}
+	for _, editor := range expired.Editors {
+		editors := updatedData.ChannelEditors[editor.ChannelID]
+		delete(editors, editor.UserID)
+		if len(editors) == 0 {
+			delete(updatedData.ChannelEditors, editor.ChannelID)
+		}
+	}
+	if err := s.persistDataset(updatedData); err != nil {
+		return ExpiredChannelGrants{}, err
+	}
+	s.data = updatedData
+	sortChannelModerators(expired.Moderators)
+	sortChannelEditors(expired.Editors)
+	return expired, nil
+}
@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+
+	"bitriver-live/internal/models"
+)
+
+// ChannelFilter narrows a channel listing. Query matches titles, owner
+// display names, and tags. Language keeps only channels streaming in that
+// language, so channels without one are left out while it is set.
+// ExcludeWarnings drops channels carrying any of the listed content
+// warnings.
+type ChannelFilter struct {
+	OwnerID         string
+	Query           string
+	Language        string
+	ExcludeWarnings []string
+}
+
+// normalizeChannelLanguage validates a BCP-47 tag against
+// models.SupportedChannelLanguages, ignoring case and accepting "_" for "-",
+// and returns it in canonical case. Blank values clear the language.
+func normalizeChannelLanguage(value string) (string, error) {
+	tag := strings.ReplaceAll(strings.TrimSpace(value), "_", "-")
+	if tag == "" {
+		return "", nil
+	}
+	for _, supported := range models.SupportedChannelLanguages {
+		if strings.EqualFold(tag, supported) {
+			return supported, nil
+		}
+	}
+	return "", invalid("language", "unsupported language %s", tag)
+}
+
+// normalizeContentWarnings validates content warnings against
+// models.ContentWarnings and returns them lowercased, deduplicated, and
+// sorted. It returns nil when there are none.
+func normalizeContentWarnings(values []string) ([]string, error) {
+	if len(values) == 0 {
+		return nil, nil
+	}
+	seen := make(map[string]struct{}, len(values))
+	warnings := make([]string, 0, len(values))
+	for _, value := range values {
+		warning := strings.ToLower(strings.TrimSpace(value))
+		if warning == "" {
+			continue
+		}
+		if !isContentWarning(warning) {
+			return nil, invalid("contentWarnings", "unknown content warning %s", warning)
+		}
+		if _, ok := seen[warning]; ok {
+			continue
+		}
+		seen[warning] = struct{}{}
+		warnings = append(warnings, warning)
+	}
+	if len(warnings) == 0 {
+		return nil, nil
+	}
+	sort.Strings(warnings)
+	return warnings, nil
+}
+
+func isContentWarning(value string) bool {
+	for _, warning := range models.ContentWarnings {
+		if value == warning {
+			return true
+		}
+	}
+	return false
+}
+
+// normalized validates the filter's language and warnings and trims the rest.
+func (f ChannelFilter) normalized() (ChannelFilter, error) {
+	language, err := normalizeChannelLanguage(f.Language)
+	if err != nil {
+		return ChannelFilter{}, err
+	}
+	warnings, err := normalizeContentWarnings(f.ExcludeWarnings)
+	if err != nil {
+		return ChannelFilter{}, err
+	}
+	return ChannelFilter{
+		OwnerID:         strings.TrimSpace(f.OwnerID),
+		Query:           strings.TrimSpace(f.Query),
+		Language:        language,
+		ExcludeWarnings: warnings,
+	}, nil
+}
+
+// channelMatchesMetadata reports whether channel passes the filter's
+// language and content warning conditions.
+func channelMatchesMetadata(channel models.Channel, filter ChannelFilter) bool {
+	if filter.Language != "" && channel.Language != filter.Language {
+		return false
+	}
+	for _, excluded := range filter.ExcludeWarnings {
+		for _, warning := range channel.ContentWarnings {
+			if warning == excluded {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// scannedContentWarnings maps the empty array Postgres stores for a channel
+// without warnings to nil, matching the JSON repository.
+func scannedContentWarnings(values []string) []string {
+	if len(values) == 0 {
+		return nil
+	}
+	return append([]string(nil), values...)
+}
+
+// nonNilStrings returns values, or an empty slice in place of nil, for
+// writing to NOT NULL array columns.
+func nonNilStrings(values []string) []string {
+	if values == nil {
+		return []string{}
+	}
+	return values
+}
@@ -0,0 +1,87 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestNormalizeChannelLanguage(t *testing.T) {
+	cases := []struct {
+		input string
+		want  string
+		ok    bool
+	}{
+		{"", "", true},
+		{"  ", "", true},
+		{"en", "en", true},
+		{"EN", "en", true},
+		{"pt-br", "pt-BR", true},
+		{"pt_BR", "pt-BR", true},
+		{"zh-hant", "zh-Hant", true},
+		{"es-419", "es-419", true},
+		{"en-", "", false},
+		{"english", "", false},
+		{"tlh", "", false},
+		{"en-US-x-private", "", false},
+	}
+	for _, tc := range cases {
+		got, err := normalizeChannelLanguage(tc.input)
+		if tc.ok != (err == nil) || got != tc.want {
+			t.Fatalf("normalizeChannelLanguage(%q): expected %q (ok %v), got %q err %v", tc.input, tc.want, tc.ok, got, err)
+		}
+		if err != nil && !errors.Is(err, ErrValidation) {
+			t.Fatalf("normalizeChannelLanguage(%q): expected a validation error, got %v", tc.input, err)
+		}
+	}
+}
+
+func TestNormalizeContentWarnings(t *testing.T) {
+	cases := []struct {
+		input []string
+		want  string
+		ok    bool
+	}{
+		{nil, "", true},
+		{[]string{" ", ""}, "", true},
+		{[]string{"violence"}, "violence", true},
+		{[]string{"Strong_Language", "gambling", "GAMBLING"}, "gambling,strong_language", true},
+		{[]string{"flashing_lights", "drugs", "sexual_themes"}, "drugs,flashing_lights,sexual_themes", true},
+		{[]string{"violence", "spoilers"}, "", false},
+		{[]string{"strong language"}, "", false},
+	}
+	for _, tc := range cases {
+		got, err := normalizeContentWarnings(tc.input)
+		if tc.ok != (err == nil) || strings.Join(got, ",") != tc.want {
+			t.Fatalf("normalizeContentWarnings(%q): expected %q (ok %v), got %v err %v", tc.input, tc.want, tc.ok, got, err)
+		}
+		if tc.want == "" && got != nil {
+			t.Fatalf("normalizeContentWarnings(%q): expected nil, got %#v", tc.input, got)
+		}
+	}
+}
+
+func TestChannelMetadataSurvivesReload(t *testing.T) {
+	store := newTestStore(t)
+	owner, err := store.CreateUser(CreateUserParams{DisplayName: "Owner", Email: "owner@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Metadata", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	language, warnings := "ja", []string{"violence", "flashing_lights"}
+	if _, err := store.UpdateChannel(channel.ID, ChannelUpdate{Language: &language, ContentWarnings: &warnings}); err != nil {
+		t.Fatalf("UpdateChannel: %v", err)
+	}
+
+	reloaded, err := NewStorage(store.filePath)
+	if err != nil {
+		t.Fatalf("reload store: %v", err)
+	}
+	loaded, ok := reloaded.GetChannel(channel.ID)
+	if !ok || loaded.Language != "ja" || strings.Join(loaded.ContentWarnings, ",") != "flashing_lights,violence" {
+		t.Fatalf("expected the metadata to survive a reload, got %q %v", loaded.Language, loaded.ContentWarnings)
+	}
+}
@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/models"
+)
+
+// GrantChannelModerator records that the user may moderate the channel's chat
+// until expiresAt, or indefinitely when expiresAt is nil. Any account can be
+// made a channel moderator, which suits guests brought on for a single
+// stream. Granting an existing moderator keeps the original grant and only
+// updates its expiry.
+func (s *Storage) GrantChannelModerator(channelID, userID, grantedBy string, expiresAt *time.Time) (models.ChannelModerator, error) {
+	channelID = strings.TrimSpace(channelID)
+	userID = strings.TrimSpace(userID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.data.Channels[channelID]; !ok {
+		return models.ChannelModerator{}, notFound("channel", channelID)
+	}
+	if _, ok := s.data.Users[userID]; !ok {
+		return models.ChannelModerator{}, notFound("user", userID)
+	}
+	now := s.grantTime()
+	expires, err := normalizeGrantExpiry(now, expiresAt)
+	if err != nil {
+		return models.ChannelModerator{}, err
+	}
+
+	moderator := models.ChannelModerator{
+		ChannelID: channelID,
+		UserID:    userID,
+		GrantedBy: strings.TrimSpace(grantedBy),
+		GrantedAt: now,
+		ExpiresAt: expires,
+	}
+	if existing, ok := s.data.ChannelModerators[channelID][userID]; ok && existing.ActiveAt(now) {
+		if sameGrantExpiry(existing.ExpiresAt, expires) {
+			return existing, nil
+		}
+		moderator.GrantedBy = existing.GrantedBy
+		moderator.GrantedAt = existing.GrantedAt
+	}
+
+	updatedData := cloneDataset(s.data)
+	if updatedData.ChannelModerators == nil {
+		updatedData.ChannelModerators = make(map[string]map[string]models.ChannelModerator)
+	}
+	moderators := updatedData.ChannelModerators[channelID]
+	if moderators == nil {
+		moderators = make(map[string]models.ChannelModerator)
+	}
+	moderators[userID] = moderator
+	updatedData.ChannelModerators[channelID] = moderators
+
+	if err := s.persistDataset(updatedData); err != nil {
+		return models.ChannelModerator{}, err
+	}
+	s.data = updatedData
+	return moderator, nil
+}
+
+// RevokeChannelModerator removes the user's moderator grant on the channel.
+// The operation is idempotent.
+func (s *Storage) RevokeChannelModerator(channelID, userID string) error {
+	channelID = strings.TrimSpace(channelID)
+	userID = strings.TrimSpace(userID)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.data.Channels[channelID]; !ok {
+		return notFound("channel", channelID)
+	}
+	if _, ok := s.data.ChannelModerators[channelID][userID]; !ok {
+		return nil
+	}
+
+	updatedData := cloneDataset(s.data)
+	moderators := updatedData.ChannelModerators[channelID]
+	delete(moderators, userID)
+	if len(moderators) == 0 {
+		delete(updatedData.ChannelModerators, channelID)
+	}
+
+	if err := s.persistDataset(updatedData); err != nil {
+		return err
+	}
+	s.data = updatedData
+	return nil
+}
+
+// ListChannelModerators returns the channel's unexpired moderator grants,
+// oldest first.
+func (s *Storage) ListChannelModerators(channelID string) ([]models.ChannelModerator, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.data.Channels[channelID]; !ok {
+		return nil, notFound("channel", channelID)
+	}
+	now := s.grantTime()
+	moderators := make([]models.ChannelModerator, 0, len(s.data.ChannelModerators[channelID]))
+	for _, moderator := range s.data.ChannelModerators[channelID] {
+		if moderator.ActiveAt(now) {
+			moderators = append(moderators, moderator)
+		}
+	}
+	sortChannelModerators(moderators)
+	return moderators, nil
+}
+
+// IsChannelModerator reports whether the user holds an unexpired moderator
+// grant on the channel.
+func (s *Storage) IsChannelModerator(channelID, userID string) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	return s.channelModeratorLocked(channelID, userID)
+}
+
+// channelModeratorLocked is IsChannelModerator for callers already holding
+// s.mu.
+func (s *Storage) channelModeratorLocked(channelID, userID string) bool {
+	moderator, ok := s.data.ChannelModerators[channelID][userID]
+	return ok && moderator.ActiveAt(s.grantTime())
+}
+
+func sortChannelModerators(moderators []models.ChannelModerator) {
+	sort.Slice(moderators, func(i, j int) bool {
+		if moderators[i].GrantedAt.Equal(moderators[j].GrantedAt) {
+			return moderators[i].UserID < moderators[j].UserID
+		}
+		return moderators[i].GrantedAt.Before(moderators[j].GrantedAt)
+	})
+}
@@ -0,0 +1,269 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/models"
+)
+
+// Channel slug rules. Slugs are 3 to 40 lowercase letters, digits, or
+// single hyphens and start and end with a letter or digit.
+const (
+	MinChannelSlugLength = 3
+	MaxChannelSlugLength = 40
+)
+
+// ChannelSlugChangeCooldown is how long an owner waits between slug changes.
+const ChannelSlugChangeCooldown = 30 * 24 * time.Hour
+
+// MaxChannelSlugRedirects is how many of its earlier slugs a channel keeps
+// redirecting; the oldest is released when another is added.
+const MaxChannelSlugRedirects = 5
+
+var (
+	// ErrChannelSlugInvalid is returned for slugs that break the length or
+	// character rules.
+	ErrChannelSlugInvalid = errors.New("slug must be 3 to 40 lowercase letters, digits, or single hyphens and start and end with a letter or digit")
+	// ErrChannelSlugReserved is returned for slugs kept for the platform.
+	ErrChannelSlugReserved = errors.New("slug is reserved")
+	// ErrChannelSlugTaken is returned when another channel uses the slug or
+	// still redirects from it.
+	ErrChannelSlugTaken = conflict("slug", "slug is already taken")
+	// ErrChannelSlugCooldown is returned when the channel's slug was chosen
+	// less than ChannelSlugChangeCooldown ago.
+	ErrChannelSlugCooldown = errors.New("slug was changed too recently")
+)
+
+var channelSlugPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+
+// channelSlugSeparators matches the runs of characters derived slugs drop.
+// deploy/migrations/0058_channel_slugs.sql applies the same rule in SQL.
+var channelSlugSeparators = regexp.MustCompile(`[^a-z0-9]+`)
+
+// ChannelSlugMatch is a channel found by slug. Redirected is set when the
+// slug is one the channel used before, in which case clients should move to
+// Channel.Slug.
+type ChannelSlugMatch struct {
+	Channel    models.Channel
+	Redirected bool
+}
+
+// NormalizeChannelSlug trims and lowercases slug and checks it against the
+// length, character, and reserved-word rules. Slugs share the reserved words
+// of usernames. It does not check uniqueness.
+func NormalizeChannelSlug(slug string) (string, error) {
+	slug = channelSlugKey(slug)
+	if len(slug) < MinChannelSlugLength || len(slug) > MaxChannelSlugLength || !channelSlugPattern.MatchString(slug) {
+		return "", ErrChannelSlugInvalid
+	}
+	if usernameReserved(slug) {
+		return "", fmt.Errorf("%w: %s", ErrChannelSlugReserved, slug)
+	}
+	return slug, nil
+}
+
+// channelSlugKey is the form slugs are stored and compared in.
+func channelSlugKey(slug string) string {
+	return strings.ToLower(strings.TrimSpace(slug))
+}
+
+// channelSlugBase turns a channel title into a candidate slug, or "" when
+// too little of it is usable.
+func channelSlugBase(title string) string {
+	base := strings.Trim(channelSlugSeparators.ReplaceAllString(strings.ToLower(title), "-"), "-")
+	if len(base) > MaxChannelSlugLength {
+		base = strings.TrimRight(base[:MaxChannelSlugLength], "-")
+	}
+	if len(base) < MinChannelSlugLength {
+		return ""
+	}
+	return base
+}
+
+// deriveChannelSlug picks an unused slug for a channel from its title, else
+// "channel". Taken or reserved candidates get a numeric suffix, starting at
+// 2.
+func deriveChannelSlug(title string, taken func(slug string) (bool, error)) (string, error) {
+	base := channelSlugBase(title)
+	if base == "" {
+		base = "channel"
+	}
+	candidate := base
+	for suffix := 2; ; suffix++ {
+		if !usernameReserved(candidate) {
+			inUse, err := taken(candidate)
+			if err != nil {
+				return "", err
+			}
+			if !inUse {
+				return candidate, nil
+			}
+		}
+		tail := "-" + strconv.Itoa(suffix)
+		candidate = strings.TrimRight(base[:min(len(base), MaxChannelSlugLength-len(tail))], "-") + tail
+	}
+}
+
+// checkChannelSlugCooldown reports whether channel's slug may change at now.
+// A derived slug may be replaced straight away.
+func checkChannelSlugCooldown(channel models.Channel, now time.Time) error {
+	if channel.SlugChangedAt == nil {
+		return nil
+	}
+	if next := channel.SlugChangedAt.Add(ChannelSlugChangeCooldown); now.Before(next) {
+		return fmt.Errorf("%w; it can be changed again after %s", ErrChannelSlugCooldown, next.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// backfillChannelSlugs assigns derived slugs to channels stored before slugs
+// existed, oldest channel first, skipping slugs in use as redirects. It
+// reports whether any channel was changed.
+func backfillChannelSlugs(channels map[string]models.Channel, redirects map[string]models.ChannelSlugRedirect) bool {
+	used := make(map[string]struct{}, len(channels)+len(redirects))
+	for slug := range redirects {
+		used[slug] = struct{}{}
+	}
+	var missing []string
+	for key, channel := range channels {
+		if channel.Slug == "" {
+			missing = append(missing, key)
+			continue
+		}
+		used[channelSlugKey(channel.Slug)] = struct{}{}
+	}
+	if len(missing) == 0 {
+		return false
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		a, b := channels[missing[i]], channels[missing[j]]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return missing[i] < missing[j]
+	})
+	for _, key := range missing {
+		channel := channels[key]
+		slug, _ := deriveChannelSlug(channel.Title, func(candidate string) (bool, error) {
+			_, inUse := used[candidate]
+			return inUse, nil
+		})
+		used[slug] = struct{}{}
+		channel.Slug = slug
+		channels[key] = channel
+	}
+	return true
+}
+
+// channelSlugTaken reports whether a channel other than exceptID uses slug
+// or redirects from it.
+func channelSlugTaken(channels map[string]models.Channel, redirects map[string]models.ChannelSlugRedirect, slug, exceptID string) bool {
+	if redirect, ok := redirects[slug]; ok && redirect.ChannelID != exceptID {
+		return true
+	}
+	for id, channel := range channels {
+		if id != exceptID && channel.Slug == slug {
+			return true
+		}
+	}
+	return false
+}
+
+// pruneChannelSlugRedirects drops channelID's oldest redirects beyond
+// MaxChannelSlugRedirects.
+func pruneChannelSlugRedirects(redirects map[string]models.ChannelSlugRedirect, channelID string) {
+	var owned []models.ChannelSlugRedirect
+	for _, redirect := range redirects {
+		if redirect.ChannelID == channelID {
+			owned = append(owned, redirect)
+		}
+	}
+	if len(owned) <= MaxChannelSlugRedirects {
+		return
+	}
+	sort.Slice(owned, func(i, j int) bool {
+		if !owned[i].CreatedAt.Equal(owned[j].CreatedAt) {
+			return owned[i].CreatedAt.After(owned[j].CreatedAt)
+		}
+		return owned[i].Slug < owned[j].Slug
+	})
+	for _, redirect := range owned[MaxChannelSlugRedirects:] {
+		delete(redirects, redirect.Slug)
+	}
+}
+
+// FindChannelBySlug looks up a channel by its current slug, then by the
+// slugs it redirects from, ignoring case.
+func (s *Storage) FindChannelBySlug(slug string) (ChannelSlugMatch, bool) {
+	key := channelSlugKey(slug)
+	if key == "" {
+		return ChannelSlugMatch{}, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, channel := range s.data.Channels {
+		if channel.Slug == key {
+			return ChannelSlugMatch{Channel: channel}, true
+		}
+	}
+	redirect, ok := s.data.ChannelSlugRedirects[key]
+	if !ok {
+		return ChannelSlugMatch{}, false
+	}
+	channel, ok := s.data.Channels[redirect.ChannelID]
+	if !ok {
+		return ChannelSlugMatch{}, false
+	}
+	return ChannelSlugMatch{Channel: channel, Redirected: true}, true
+}
+
+// ChangeChannelSlug sets the channel's slug, keeping the old one as a
+// redirect. Once chosen, a slug can change once per
+// ChannelSlugChangeCooldown; setting the current slug again is a no-op, and
+// a channel may take back a slug it still redirects from.
+func (s *Storage) ChangeChannelSlug(id, slug string, now time.Time) (models.Channel, error) {
+	slug, err := NormalizeChannelSlug(slug)
+	if err != nil {
+		return models.Channel{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	channel, ok := s.data.Channels[id]
+	if !ok {
+		return models.Channel{}, notFound("channel", id)
+	}
+	if channel.Slug == slug {
+		return channel, nil
+	}
+	if err := checkChannelSlugCooldown(channel, now); err != nil {
+		return models.Channel{}, err
+	}
+	if channelSlugTaken(s.data.Channels, s.data.ChannelSlugRedirects, slug, id) {
+		return models.Channel{}, ErrChannelSlugTaken
+	}
+
+	updatedData := cloneDataset(s.data)
+	changedAt := now.UTC()
+	delete(updatedData.ChannelSlugRedirects, slug)
+	if channel.Slug != "" {
+		updatedData.ChannelSlugRedirects[channel.Slug] = models.ChannelSlugRedirect{Slug: channel.Slug, ChannelID: id, CreatedAt: changedAt}
+		pruneChannelSlugRedirects(updatedData.ChannelSlugRedirects, id)
+	}
+	channel.Slug = slug
+	channel.SlugChangedAt = &changedAt
+	channel.UpdatedAt = changedAt
+	updatedData.Channels[id] = channel
+	if err := s.persistDataset(updatedData); err != nil {
+		return models.Channel{}, err
+	}
+	s.data = updatedData
+	return channel, nil
+}
@@ -0,0 +1,78 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStorageBackfillsChannelSlugsOnLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	legacy := `{
+  "users": {
+    "u1": {"id": "u1", "displayName": "Owner", "username": "owner", "email": "owner@example.com", "createdAt": "2024-01-01T00:00:00Z"}
+  },
+  "channels": {
+    "c1": {"id": "c1", "ownerId": "u1", "streamKeyHash": "h1", "title": "Speedruns & Chill", "tags": [], "liveState": "offline", "createdAt": "2024-01-01T00:00:00Z"},
+    "c2": {"id": "c2", "ownerId": "u1", "streamKeyHash": "h2", "title": "speedruns chill", "tags": [], "liveState": "offline", "createdAt": "2024-01-02T00:00:00Z"},
+    "c3": {"id": "c3", "ownerId": "u1", "streamKeyHash": "h3", "title": "Support", "tags": [], "liveState": "offline", "createdAt": "2024-01-03T00:00:00Z"},
+    "c4": {"id": "c4", "ownerId": "u1", "streamKeyHash": "h4", "title": "☃", "tags": [], "liveState": "offline", "createdAt": "2024-01-04T00:00:00Z"},
+    "c5": {"id": "c5", "ownerId": "u1", "streamKeyHash": "h5", "title": "Kept", "slug": "speedruns-chill-3", "tags": [], "liveState": "offline", "createdAt": "2024-01-05T00:00:00Z"}
+  },
+  "channelSlugRedirects": {
+    "speedruns-chill-2": {"slug": "speedruns-chill-2", "channelId": "c5", "createdAt": "2024-02-01T00:00:00Z"}
+  }
+}`
+	if err := os.WriteFile(path, []byte(legacy), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	store, err := NewStorage(path)
+	if err != nil {
+		t.Fatalf("NewStorage: %v", err)
+	}
+	want := map[string]string{
+		"c1": "speedruns-chill",
+		// speedruns-chill-2 still redirects to c5 and c5 holds
+		// speedruns-chill-3, so the suffix moves on.
+		"c2": "speedruns-chill-4",
+		"c3": "support-2",
+		"c4": "channel",
+		"c5": "speedruns-chill-3",
+	}
+	for id, slug := range want {
+		channel, ok := store.GetChannel(id)
+		if !ok {
+			t.Fatalf("expected channel %s to load", id)
+		}
+		if channel.Slug != slug || channel.SlugChangedAt != nil {
+			t.Errorf("channel %s: expected slug %q and no change time, got %+v", id, slug, channel)
+		}
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if !strings.Contains(string(raw), `"slug": "speedruns-chill-4"`) {
+		t.Fatal("expected the backfilled slugs to be persisted")
+	}
+}
+
+func TestDeriveChannelSlugKeepsSuffixWithinLimit(t *testing.T) {
+	long := strings.Repeat("a", 38) + " b " + strings.Repeat("c", 10)
+	taken := map[string]bool{strings.Repeat("a", 38) + "-b": true}
+	slug, err := deriveChannelSlug(long, func(candidate string) (bool, error) { return taken[candidate], nil })
+	if err != nil {
+		t.Fatalf("deriveChannelSlug: %v", err)
+	}
+	// Cutting the base to make room for "-2" leaves a trailing hyphen,
+	// which is dropped rather than doubled.
+	if want := strings.Repeat("a", 38) + "-2"; slug != want {
+		t.Fatalf("expected %q, got %q", want, slug)
+	}
+	if _, err := NormalizeChannelSlug(slug); err != nil {
+		t.Fatalf("expected the derived slug to be valid: %v", err)
+	}
+}
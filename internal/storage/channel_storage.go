@@ -0,0 +1,238 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"bitriver-live/internal/models"
+)
+
+// effectiveStorageQuota is the quota a channel is held to: its override
+// when an admin set one, otherwise the platform default. Zero is unlimited.
+func effectiveStorageQuota(override *int64, platform int64) int64 {
+	if override != nil {
+		return *override
+	}
+	if platform < 0 {
+		return 0
+	}
+	return platform
+}
+
+// exceedsStorageQuota reports whether adding size bytes to used breaks
+// quota. A quota of zero is unlimited. A channel that is already full cannot
+// add anything, even an item of unknown size.
+func exceedsStorageQuota(used, size, quota int64) bool {
+	if quota <= 0 {
+		return false
+	}
+	return used >= quota || size > quota-used
+}
+
+// overStorageQuota reports whether a channel using used bytes is over
+// quota. Recordings are checked with this rather than exceedsStorageQuota:
+// they are kept either way, and one that exactly fills the channel fits.
+func overStorageQuota(used, quota int64) bool {
+	return quota > 0 && used > quota
+}
+
+// normalizeStorageQuota validates an admin quota override. Nil clears the
+// override and zero lifts the quota for the channel.
+func normalizeStorageQuota(quota *int64) (*int64, error) {
+	if quota == nil {
+		return nil, nil
+	}
+	if *quota < 0 {
+		return nil, fmt.Errorf("quotaBytes must be zero or greater")
+	}
+	value := *quota
+	return &value, nil
+}
+
+// storageQuotaError reports why a channel has no room for size more bytes.
+func storageQuotaError(used, size, quota int64) error {
+	return fmt.Errorf("%w: channel uses %d of %d bytes and needs %d more", ErrStorageQuotaExceeded, used, quota, size)
+}
+
+// sortStorageItems orders a storage breakdown largest first, so the items
+// worth deleting to free space come first.
+func sortStorageItems(items []ChannelStorageItem) {
+	sort.Slice(items, func(i, j int) bool {
+		if items[i].SizeBytes != items[j].SizeBytes {
+			return items[i].SizeBytes > items[j].SizeBytes
+		}
+		if !items[i].CreatedAt.Equal(items[j].CreatedAt) {
+			return items[i].CreatedAt.After(items[j].CreatedAt)
+		}
+		return items[i].ID < items[j].ID
+	})
+}
+
+// rebuildChannelStorage totals the recordings and uploads in data per
+// channel. Datasets written before storage accounting existed start from
+// these totals.
+func rebuildChannelStorage(data dataset, now time.Time) map[string]models.ChannelStorage {
+	totals := make(map[string]models.ChannelStorage)
+	add := func(channelID string, size int64) {
+		if _, ok := data.Channels[channelID]; !ok || size <= 0 {
+			return
+		}
+		entry := totals[channelID]
+		entry.ChannelID = channelID
+		entry.UsedBytes += size
+		entry.UpdatedAt = now
+		totals[channelID] = entry
+	}
+	for _, recording := range data.Recordings {
+		add(recording.ChannelID, recording.SizeBytes)
+	}
+	for _, upload := range data.Uploads {
+		add(upload.ChannelID, upload.StorageBytes())
+	}
+	return totals
+}
+
+// channelStorageLocked returns the channel's usage and the quota it is held
+// to.
+func (s *Storage) channelStorageLocked(channelID string) (models.ChannelStorage, int64) {
+	entry, ok := s.data.ChannelStorage[channelID]
+	if !ok {
+		entry = models.ChannelStorage{ChannelID: channelID}
+	}
+	return entry, effectiveStorageQuota(entry.QuotaBytes, s.storageQuota)
+}
+
+// adjustChannelStorageLocked moves the channel's running total by delta. The
+// total never drops below zero, so deleting an item that predates
+// accounting cannot leave the channel with negative usage.
+func (s *Storage) adjustChannelStorageLocked(channelID string, delta int64, now time.Time) {
+	if delta == 0 || channelID == "" {
+		return
+	}
+	if s.data.ChannelStorage == nil {
+		s.data.ChannelStorage = make(map[string]models.ChannelStorage)
+	}
+	entry, _ := s.channelStorageLocked(channelID)
+	entry.UsedBytes += delta
+	if entry.UsedBytes < 0 {
+		entry.UsedBytes = 0
+	}
+	entry.UpdatedAt = now
+	s.data.ChannelStorage[channelID] = entry
+}
+
+// releaseOverQuotaRecordingsLocked clears the flag on the channel's
+// over-quota recordings once its usage is back within its quota, publishing
+// them when the channel auto-publishes.
+func (s *Storage) releaseOverQuotaRecordingsLocked(channelID string, now time.Time) {
+	if entry, quota := s.channelStorageLocked(channelID); overStorageQuota(entry.UsedBytes, quota) {
+		return
+	}
+	channel, ok := s.data.Channels[channelID]
+	if !ok {
+		return
+	}
+	publish := channel.RecordingPolicy == models.RecordingPolicyAutoPublish
+	for id, recording := range s.data.Recordings {
+		if recording.ChannelID != channelID || !recording.OverQuota {
+			continue
+		}
+		recording.OverQuota = false
+		if publish && recording.PublishedAt == nil {
+			publishedAt := now
+			recording.PublishedAt = &publishedAt
+			recording.RetainUntil = s.recordingDeadline(now, true)
+		}
+		s.data.Recordings[id] = recording
+	}
+}
+
+// ChannelStorageUsage reports how much storage the channel uses, the quota
+// it is held to, and the recordings and uploads that make up its usage.
+func (s *Storage) ChannelStorageUsage(channelID string) (ChannelStorageReport, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.data.Channels[channelID]; !ok {
+		return ChannelStorageReport{}, notFound("channel", channelID)
+	}
+	entry, quota := s.channelStorageLocked(channelID)
+	report := ChannelStorageReport{
+		ChannelID:  channelID,
+		UsedBytes:  entry.UsedBytes,
+		QuotaBytes: quota,
+		Items:      make([]ChannelStorageItem, 0),
+	}
+	if entry.QuotaBytes != nil {
+		override := *entry.QuotaBytes
+		report.QuotaOverride = &override
+	}
+	for _, recording := range s.data.Recordings {
+		if recording.ChannelID != channelID {
+			continue
+		}
+		report.Items = append(report.Items, ChannelStorageItem{
+			Kind:      StorageItemRecording,
+			ID:        recording.ID,
+			Title:     recording.Title,
+			SizeBytes: recording.SizeBytes,
+			OverQuota: recording.OverQuota,
+			CreatedAt: recording.CreatedAt,
+		})
+	}
+	for _, upload := range s.data.Uploads {
+		if upload.ChannelID != channelID {
+			continue
+		}
+		report.Items = append(report.Items, ChannelStorageItem{
+			Kind:      StorageItemUpload,
+			ID:        upload.ID,
+			Title:     upload.Title,
+			SizeBytes: upload.StorageBytes(),
+			CreatedAt: upload.CreatedAt,
+		})
+	}
+	sortStorageItems(report.Items)
+	return report, nil
+}
+
+// SetChannelStorageQuota sets the admin override of the channel's storage
+// quota. Nil returns the channel to the platform default and zero lifts the
+// quota. Raising the quota releases recordings flagged as over quota once
+// the channel fits again.
+func (s *Storage) SetChannelStorageQuota(channelID string, quotaBytes *int64) (models.ChannelStorage, error) {
+	quota, err := normalizeStorageQuota(quotaBytes)
+	if err != nil {
+		return models.ChannelStorage{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.data.Channels[channelID]; !ok {
+		return models.ChannelStorage{}, notFound("channel", channelID)
+	}
+	s.ensureDatasetInitializedLocked()
+	snapshot := cloneDataset(s.data)
+	now := time.Now().UTC()
+	entry, _ := s.channelStorageLocked(channelID)
+	entry.QuotaBytes = quota
+	entry.UpdatedAt = now
+	s.data.ChannelStorage[channelID] = entry
+	s.releaseOverQuotaRecordingsLocked(channelID, now)
+	if err := s.persist(); err != nil {
+		s.data = snapshot
+		return models.ChannelStorage{}, err
+	}
+	return cloneChannelStorage(entry), nil
+}
+
+func cloneChannelStorage(entry models.ChannelStorage) models.ChannelStorage {
+	cloned := entry
+	if entry.QuotaBytes != nil {
+		quota := *entry.QuotaBytes
+		cloned.QuotaBytes = &quota
+	}
+	return cloned
+}
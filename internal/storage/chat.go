@@ -8,6 +8,7 @@ import (
 	"strings"
 	"time"
 
+	"bitriver-live/internal/chat"
 	"bitriver-live/internal/models"
 )
 
@@ -21,6 +22,7 @@ func initChatDataset(ds *dataset) {
 	ds.ChatTimeoutReasons = make(map[string]map[string]string)
 	ds.ChatTimeoutIssuedAt = make(map[string]map[string]time.Time)
 	ds.ChatReports = make(map[string]models.ChatReport)
+	ds.ModerationActions = make(map[string]models.ModerationAction)
 }
 
 func (s *Storage) ensureChatDatasetInitializedLocked() {
@@ -51,6 +53,9 @@ func (s *Storage) ensureChatDatasetInitializedLocked() {
 	if s.data.ChatReports == nil {
 		s.data.ChatReports = make(map[string]models.ChatReport)
 	}
+	if s.data.ModerationActions == nil {
+		s.data.ModerationActions = make(map[string]models.ModerationAction)
+	}
 }
 
 func cloneChatData(src dataset, clone *dataset) {
@@ -174,9 +179,20 @@ func cloneChatData(src dataset, clone *dataset) {
 				resolved := *report.ResolvedAt
 				cloned.ResolvedAt = &resolved
 			}
+			if report.Claimant != nil {
+				claimant := *report.Claimant
+				cloned.Claimant = &claimant
+			}
 			clone.ChatReports[id] = cloned
 		}
 	}
+
+	if src.ModerationActions != nil {
+		clone.ModerationActions = make(map[string]models.ModerationAction, len(src.ModerationActions))
+		for id, action := range src.ModerationActions {
+			clone.ModerationActions[id] = cloneModerationAction(action)
+		}
+	}
 }
 
 func (s *Storage) ensureBanMetadata(channelID string) {
@@ -217,28 +233,54 @@ func (s *Storage) ensureTimeoutMetadata(channelID string) {
 
 // Chat operations
 
-func (s *Storage) CreateChatMessage(channelID, userID, content string) (models.ChatMessage, error) {
+// CreateChatMessage stores a chat message as chat.ProcessContent leaves it
+// under the channel's content settings. When clientMessageID is set and the
+// same user sent a message with it to the channel within
+// chat.ClientMessageIDWindow, that message is returned instead.
+func (s *Storage) CreateChatMessage(channelID, userID, content, clientMessageID string) (models.ChatMessage, error) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	if _, ok := s.data.Channels[channelID]; !ok {
-		return models.ChatMessage{}, fmt.Errorf("channel %s not found", channelID)
+	channel, ok := s.data.Channels[channelID]
+	if !ok {
+		return models.ChatMessage{}, notFound("channel", channelID)
 	}
-	if _, ok := s.data.Users[userID]; !ok {
-		return models.ChatMessage{}, fmt.Errorf("user %s not found", userID)
+	user, ok := s.data.Users[userID]
+	if !ok {
+		return models.ChatMessage{}, notFound("user", userID)
 	}
 
 	if err := s.ensureChatAccessLocked(channelID, userID); err != nil {
 		return models.ChatMessage{}, err
 	}
+	now := time.Now().UTC()
+	subscribed := s.subscribedLocked(channelID, userID, now)
+	moderator := s.channelModeratorLocked(channelID, userID)
+	if !chat.ChatAllowed(channel, user, subscribed, moderator) {
+		return models.ChatMessage{}, chat.ErrSubscribersOnly
+	}
 
-	trimmed := strings.TrimSpace(content)
-	if trimmed == "" {
+	processed := chat.ProcessContent(content, channel.ChatMaxCapsPercent)
+	if processed.Content == "" {
 		return models.ChatMessage{}, errors.New("message content cannot be empty")
 	}
-	if len([]rune(trimmed)) > MaxChatMessageLength {
+	if len([]rune(processed.Content)) > MaxChatMessageLength {
 		return models.ChatMessage{}, fmt.Errorf("message content exceeds %d characters", MaxChatMessageLength)
 	}
+	clientMessageID, err := chat.NormalizeClientMessageID(clientMessageID)
+	if err != nil {
+		return models.ChatMessage{}, err
+	}
+
+	if len(processed.URLs) > 0 {
+		_, following := s.data.Follows[userID][channelID]
+		if !chat.LinksAllowed(channel, user, following, subscribed, moderator) {
+			return models.ChatMessage{}, chat.ErrLinksRestricted
+		}
+	}
+	if original, ok := s.chatMessageByClientIDLocked(channelID, userID, clientMessageID, now); ok {
+		return original, nil
+	}
 
 	id, err := generateID()
 	if err != nil {
@@ -246,11 +288,12 @@ func (s *Storage) CreateChatMessage(channelID, userID, content string) (models.C
 	}
 
 	message := models.ChatMessage{
-		ID:        id,
-		ChannelID: channelID,
-		UserID:    userID,
-		Content:   trimmed,
-		CreatedAt: time.Now().UTC(),
+		ID:              id,
+		ChannelID:       channelID,
+		UserID:          userID,
+		Content:         processed.Content,
+		ClientMessageID: clientMessageID,
+		CreatedAt:       now,
 	}
 
 	s.data.ChatMessages[id] = message
@@ -258,10 +301,63 @@ func (s *Storage) CreateChatMessage(channelID, userID, content string) (models.C
 		delete(s.data.ChatMessages, id)
 		return models.ChatMessage{}, err
 	}
+	s.indexChatClientMessageLocked(message, now)
 
 	return message, nil
 }
 
+// chatMessageByClientIDLocked returns the message userID sent to channelID
+// with clientMessageID if it is still within chat.ClientMessageIDWindow.
+func (s *Storage) chatMessageByClientIDLocked(channelID, userID, clientMessageID string, now time.Time) (models.ChatMessage, bool) {
+	if clientMessageID == "" {
+		return models.ChatMessage{}, false
+	}
+	key := chat.ClientMessageKey(channelID, userID, clientMessageID)
+	id, ok := s.chatClientMessages[key]
+	if !ok {
+		return models.ChatMessage{}, false
+	}
+	message, ok := s.data.ChatMessages[id]
+	if !ok || message.ClientMessageID != clientMessageID || !chat.ClientMessageIDActive(message.CreatedAt, now) {
+		delete(s.chatClientMessages, key)
+		return models.ChatMessage{}, false
+	}
+	return message, true
+}
+
+// indexChatClientMessageLocked records message under its client message ID,
+// sweeping expired entries from the index as it goes.
+func (s *Storage) indexChatClientMessageLocked(message models.ChatMessage, now time.Time) {
+	if message.ClientMessageID == "" || !chat.ClientMessageIDActive(message.CreatedAt, now) {
+		return
+	}
+	if s.chatClientMessages == nil {
+		s.chatClientMessages = make(map[string]string)
+	}
+	for key, id := range s.chatClientMessages {
+		if existing, ok := s.data.ChatMessages[id]; !ok || !chat.ClientMessageIDActive(existing.CreatedAt, now) {
+			delete(s.chatClientMessages, key)
+		}
+	}
+	s.chatClientMessages[chat.ClientMessageKey(message.ChannelID, message.UserID, message.ClientMessageID)] = message.ID
+}
+
+// rebuildChatClientMessagesLocked indexes the messages loaded from disk that
+// still reserve their client message IDs.
+func (s *Storage) rebuildChatClientMessagesLocked(now time.Time) {
+	s.chatClientMessages = make(map[string]string)
+	for _, message := range s.data.ChatMessages {
+		if message.ClientMessageID == "" || !chat.ClientMessageIDActive(message.CreatedAt, now) {
+			continue
+		}
+		key := chat.ClientMessageKey(message.ChannelID, message.UserID, message.ClientMessageID)
+		if existing, ok := s.data.ChatMessages[s.chatClientMessages[key]]; ok && !message.CreatedAt.Before(existing.CreatedAt) {
+			continue
+		}
+		s.chatClientMessages[key] = message.ID
+	}
+}
+
 func (s *Storage) ensureChatAccessLocked(channelID, userID string) error {
 	if s.isChatBannedLocked(channelID, userID) {
 		return fmt.Errorf("user is banned")
@@ -401,7 +497,7 @@ func (s *Storage) ListChatMessages(channelID string, limit int) ([]models.ChatMe
 	defer s.mu.RUnlock()
 
 	if _, ok := s.data.Channels[channelID]; !ok {
-		return nil, fmt.Errorf("channel %s not found", channelID)
+		return nil, notFound("channel", channelID)
 	}
 
 	messages := make([]models.ChatMessage, 0)
@@ -412,7 +508,10 @@ func (s *Storage) ListChatMessages(channelID string, limit int) ([]models.ChatMe
 	}
 
 	sort.Slice(messages, func(i, j int) bool {
-		return messages[i].CreatedAt.After(messages[j].CreatedAt)
+		if !messages[i].CreatedAt.Equal(messages[j].CreatedAt) {
+			return messages[i].CreatedAt.After(messages[j].CreatedAt)
+		}
+		return messages[i].ID < messages[j].ID
 	})
 
 	if limit > 0 && len(messages) > limit {
@@ -421,13 +520,183 @@ func (s *Storage) ListChatMessages(channelID string, limit int) ([]models.ChatMe
 	return messages, nil
 }
 
-// DeleteChatMessage removes a single chat message from the transcript.
-func (s *Storage) DeleteChatMessage(channelID, messageID string) error {
+// ListChatMessagesInRange returns the channel's chat messages posted within
+// the requested window, oldest first, so recordings can replay chat in sync
+// with playback.
+func (s *Storage) ListChatMessagesInRange(params ChatMessageRangeParams) ([]models.ChatMessage, error) {
+	params, err := normalizeChatMessageRangeParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.data.Channels[params.ChannelID]; !ok {
+		return nil, notFound("channel", params.ChannelID)
+	}
+
+	messages := make([]models.ChatMessage, 0)
+	for _, message := range s.data.ChatMessages {
+		if message.ChannelID != params.ChannelID {
+			continue
+		}
+		if message.CreatedAt.Before(params.Start) || !message.CreatedAt.Before(params.End) {
+			continue
+		}
+		if !params.AfterCreatedAt.IsZero() && !chatMessageAfterCursor(message, params.AfterCreatedAt, params.AfterID) {
+			continue
+		}
+		messages = append(messages, message)
+	}
+
+	sort.Slice(messages, func(i, j int) bool {
+		if messages[i].CreatedAt.Equal(messages[j].CreatedAt) {
+			return messages[i].ID < messages[j].ID
+		}
+		return messages[i].CreatedAt.Before(messages[j].CreatedAt)
+	})
+
+	if len(messages) > params.Limit {
+		messages = messages[:params.Limit]
+	}
+	return messages, nil
+}
+
+// ChatAuthors returns the name, roles, avatar, and subscription state of the
+// given users in the channel, for rendering chat history. Users that no
+// longer exist are left out.
+func (s *Storage) ChatAuthors(channelID string, userIDs []string) (map[string]chat.AuthorInfo, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.data.Channels[channelID]; !ok {
+		return nil, notFound("channel", channelID)
+	}
+	authors := make(map[string]chat.AuthorInfo, len(userIDs))
+	for _, id := range userIDs {
+		user, ok := s.data.Users[id]
+		if !ok {
+			continue
+		}
+		info := chat.AuthorInfo{ID: user.ID, DisplayName: user.DisplayName, Roles: append([]string(nil), user.Roles...)}
+		if profile, ok := s.data.Profiles[id]; ok {
+			info.AvatarURL = profile.AvatarURL
+		}
+		for _, grant := range s.userBadgesLocked(id) {
+			info.GlobalBadges = append(info.GlobalBadges, grant.Slug)
+		}
+		info.ChannelModerator = s.channelModeratorLocked(channelID, id)
+		authors[id] = info
+	}
+	now := time.Now().UTC()
+	subs := make(map[string][]models.Subscription)
+	for _, sub := range s.data.Subscriptions {
+		if _, ok := authors[sub.UserID]; ok && sub.ChannelID == channelID {
+			subs[sub.UserID] = append(subs[sub.UserID], sub)
+		}
+	}
+	for userID, userSubs := range subs {
+		standing := subscriptionStanding(channelID, userID, userSubs, now)
+		info := authors[userID]
+		info.Subscriber = standing.Active
+		info.SubscriberMonths = standing.Months
+		authors[userID] = info
+	}
+	return authors, nil
+}
+
+// HasChatted reports whether the user has any stored message in the channel.
+func (s *Storage) HasChatted(channelID, userID string) (bool, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, message := range s.data.ChatMessages {
+		if message.ChannelID == channelID && message.UserID == userID {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+// ChatterStats counts the channel's distinct chatters since the given time
+// and how many of them had never chatted in the channel before it.
+func (s *Storage) ChatterStats(channelID string, since time.Time) (ChatterStats, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.data.Channels[channelID]; !ok {
+		return ChatterStats{}, notFound("channel", channelID)
+	}
+	type chatterHistory struct {
+		before bool
+		since  bool
+	}
+	chatters := make(map[string]chatterHistory)
+	for _, message := range s.data.ChatMessages {
+		if message.ChannelID != channelID {
+			continue
+		}
+		history := chatters[message.UserID]
+		if message.CreatedAt.Before(since) {
+			history.before = true
+		} else {
+			history.since = true
+		}
+		chatters[message.UserID] = history
+	}
+	var stats ChatterStats
+	for _, history := range chatters {
+		if !history.since {
+			continue
+		}
+		stats.UniqueChatters++
+		if !history.before {
+			stats.FirstTimeChatters++
+		}
+	}
+	return stats, nil
+}
+
+func normalizeChatMessageRangeParams(params ChatMessageRangeParams) (ChatMessageRangeParams, error) {
+	params.ChannelID = strings.TrimSpace(params.ChannelID)
+	if params.ChannelID == "" {
+		return params, errors.New("channel id is required")
+	}
+	if params.Start.IsZero() || params.End.IsZero() {
+		return params, errors.New("chat window start and end are required")
+	}
+	if !params.End.After(params.Start) {
+		return params, errors.New("chat window end must be after start")
+	}
+	if params.Limit <= 0 || params.Limit > MaxChatReplayPageSize {
+		params.Limit = MaxChatReplayPageSize
+	}
+	params.Start = params.Start.UTC()
+	params.End = params.End.UTC()
+	if !params.AfterCreatedAt.IsZero() {
+		params.AfterCreatedAt = params.AfterCreatedAt.UTC()
+	}
+	return params, nil
+}
+
+// chatMessageAfterCursor reports whether message sorts after the
+// (createdAt, id) cursor used to page through replay windows.
+func chatMessageAfterCursor(message models.ChatMessage, createdAt time.Time, id string) bool {
+	if message.CreatedAt.Equal(createdAt) {
+		return message.ID > id
+	}
+	return message.CreatedAt.After(createdAt)
+}
+
+// DeleteChatMessage removes a single chat message from the transcript and
+// logs the deletion against the message's author.
+func (s *Storage) DeleteChatMessage(channelID, messageID, actorID string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	if _, ok := s.data.Channels[channelID]; !ok {
-		return fmt.Errorf("channel %s not found", channelID)
+		return notFound("channel", channelID)
 	}
 
 	message, ok := s.data.ChatMessages[messageID]
@@ -438,9 +707,14 @@ func (s *Storage) DeleteChatMessage(channelID, messageID string) error {
 		return nil
 	}
 
+	actionID, err := s.appendModerationActionLocked(deletedMessageAction(message, actorID, time.Now().UTC()))
+	if err != nil {
+		return err
+	}
 	delete(s.data.ChatMessages, messageID)
 	if err := s.persist(); err != nil {
 		s.data.ChatMessages[messageID] = message
+		delete(s.data.ModerationActions, actionID)
 		return err
 	}
 	return nil
@@ -484,7 +758,7 @@ func (s *Storage) pruneExpiredTimeoutsLocked(channelID string, now time.Time) bo
 }
 
 // ListChatRestrictions returns the current bans and timeouts for a channel.
-func (s *Storage) ListChatRestrictions(channelID string) []models.ChatRestriction {
+func (s *Storage) ListChatRestrictions(channelID string) ([]models.ChatRestriction, error) {
 	now := time.Now().UTC()
 
 	s.mu.Lock()
@@ -538,7 +812,7 @@ func (s *Storage) ListChatRestrictions(channelID string) []models.ChatRestrictio
 			slog.Error("persist pruned chat timeouts", "err", err)
 		}
 	}
-	return restrictions
+	return restrictions, nil
 }
 
 func (s *Storage) lookupBanActor(channelID, userID string) string {
@@ -584,13 +858,13 @@ func (s *Storage) CreateChatReport(channelID, reporterID, targetID, reason, mess
 	defer s.mu.Unlock()
 
 	if _, ok := s.data.Channels[channelID]; !ok {
-		return models.ChatReport{}, fmt.Errorf("channel %s not found", channelID)
+		return models.ChatReport{}, notFound("channel", channelID)
 	}
 	if _, ok := s.data.Users[reporterID]; !ok {
-		return models.ChatReport{}, fmt.Errorf("reporter %s not found", reporterID)
+		return models.ChatReport{}, notFound("reporter", reporterID)
 	}
 	if _, ok := s.data.Users[targetID]; !ok {
-		return models.ChatReport{}, fmt.Errorf("target %s not found", targetID)
+		return models.ChatReport{}, notFound("target", targetID)
 	}
 	trimmedReason := strings.TrimSpace(reason)
 	if trimmedReason == "" {
@@ -606,12 +880,15 @@ func (s *Storage) CreateChatReport(channelID, reporterID, targetID, reason, mess
 		ChannelID:   channelID,
 		ReporterID:  reporterID,
 		TargetID:    targetID,
+		SubjectType: models.ReportSubjectChatMessage,
+		Category:    models.ReportCategoryOther,
 		Reason:      trimmedReason,
 		MessageID:   strings.TrimSpace(messageID),
 		EvidenceURL: strings.TrimSpace(evidenceURL),
 		Status:      ChatReportStatusOpen,
 		CreatedAt:   now,
 	}
+	report.SubjectID = report.MessageID
 	if s.data.ChatReports == nil {
 		s.data.ChatReports = make(map[string]models.ChatReport)
 	}
@@ -629,7 +906,7 @@ func (s *Storage) ListChatReports(channelID string, includeResolved bool) ([]mod
 	defer s.mu.RUnlock()
 
 	if _, ok := s.data.Channels[channelID]; !ok {
-		return nil, fmt.Errorf("channel %s not found", channelID)
+		return nil, notFound("channel", channelID)
 	}
 	reports := make([]models.ChatReport, 0)
 	for _, report := range s.data.ChatReports {
@@ -641,12 +918,7 @@ func (s *Storage) ListChatReports(channelID string, includeResolved bool) ([]mod
 		}
 		reports = append(reports, report)
 	}
-	sort.Slice(reports, func(i, j int) bool {
-		if reports[i].CreatedAt.Equal(reports[j].CreatedAt) {
-			return reports[i].ID < reports[j].ID
-		}
-		return reports[i].CreatedAt.After(reports[j].CreatedAt)
-	})
+	sortReports(reports)
 	return reports, nil
 }
 
@@ -657,10 +929,10 @@ func (s *Storage) ResolveChatReport(reportID, resolverID, resolution string) (mo
 
 	report, ok := s.data.ChatReports[reportID]
 	if !ok {
-		return models.ChatReport{}, fmt.Errorf("report %s not found", reportID)
+		return models.ChatReport{}, notFound("report", reportID)
 	}
 	if _, ok := s.data.Users[resolverID]; !ok {
-		return models.ChatReport{}, fmt.Errorf("resolver %s not found", resolverID)
+		return models.ChatReport{}, notFound("resolver", resolverID)
 	}
 	if strings.EqualFold(report.Status, ChatReportStatusResolved) {
 		return report, nil
@@ -670,12 +942,19 @@ func (s *Storage) ResolveChatReport(reportID, resolverID, resolution string) (mo
 	if trimmed == "" {
 		trimmed = ChatReportStatusResolved
 	}
+	previous := report
 	report.Status = ChatReportStatusResolved
 	report.Resolution = trimmed
 	report.ResolverID = resolverID
 	report.ResolvedAt = &now
+	actionID, err := s.appendModerationActionLocked(resolvedReportAction(report))
+	if err != nil {
+		return models.ChatReport{}, err
+	}
 	s.data.ChatReports[reportID] = report
 	if err := s.persist(); err != nil {
+		s.data.ChatReports[reportID] = previous
+		delete(s.data.ModerationActions, actionID)
 		return models.ChatReport{}, err
 	}
 	return report, nil
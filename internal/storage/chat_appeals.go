@@ -0,0 +1,211 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"bitriver-live/internal/models"
+)
+
+const (
+	// MaxChatAppealLength is the longest appeal message, in characters.
+	MaxChatAppealLength = 1000
+	// MaxChatAppealNoteLength is the longest resolution note, in characters.
+	MaxChatAppealNoteLength = 500
+)
+
+func normalizeChatAppealMessage(message string) (string, error) {
+	message = strings.TrimSpace(message)
+	if message == "" {
+		return "", fmt.Errorf("message is required")
+	}
+	if utf8.RuneCountInString(message) > MaxChatAppealLength {
+		return "", fmt.Errorf("message must be %d characters or fewer", MaxChatAppealLength)
+	}
+	return message, nil
+}
+
+func normalizeChatAppealNote(note string) (string, error) {
+	note = strings.TrimSpace(note)
+	if utf8.RuneCountInString(note) > MaxChatAppealNoteLength {
+		return "", fmt.Errorf("note must be %d characters or fewer", MaxChatAppealNoteLength)
+	}
+	return note, nil
+}
+
+// newChatAppeal validates message and builds an open appeal against the
+// restriction currently in force, "ban" or "timeout".
+func newChatAppeal(channelID, userID, restriction, message string, now time.Time) (models.ChatAppeal, error) {
+	normalized, err := normalizeChatAppealMessage(message)
+	if err != nil {
+		return models.ChatAppeal{}, err
+	}
+	id, err := generateID()
+	if err != nil {
+		return models.ChatAppeal{}, err
+	}
+	return models.ChatAppeal{
+		ID:          id,
+		ChannelID:   channelID,
+		UserID:      userID,
+		Restriction: restriction,
+		Message:     normalized,
+		Status:      ChatAppealStatusOpen,
+		CreatedAt:   now,
+	}, nil
+}
+
+// resolveChatAppeal closes appeal as accepted or rejected by resolverID.
+func resolveChatAppeal(appeal models.ChatAppeal, resolverID string, accept bool, note string, now time.Time) (models.ChatAppeal, error) {
+	if appeal.Status != ChatAppealStatusOpen {
+		return models.ChatAppeal{}, ErrChatAppealResolved
+	}
+	normalized, err := normalizeChatAppealNote(note)
+	if err != nil {
+		return models.ChatAppeal{}, err
+	}
+	appeal.Status = ChatAppealStatusRejected
+	if accept {
+		appeal.Status = ChatAppealStatusAccepted
+	}
+	appeal.ResolverID = resolverID
+	appeal.ResolutionNote = normalized
+	appeal.ResolvedAt = &now
+	return appeal, nil
+}
+
+// sortChatAppeals orders appeals newest first.
+func sortChatAppeals(appeals []models.ChatAppeal) {
+	sort.Slice(appeals, func(i, j int) bool {
+		if appeals[i].CreatedAt.Equal(appeals[j].CreatedAt) {
+			return appeals[i].ID < appeals[j].ID
+		}
+		return appeals[i].CreatedAt.After(appeals[j].CreatedAt)
+	})
+}
+
+// activeChatRestrictionLocked reports the restriction userID is under in
+// the channel, preferring a ban over a timeout. The caller must hold s.mu.
+func (s *Storage) activeChatRestrictionLocked(channelID, userID string, now time.Time) string {
+	if _, banned := s.data.ChatBans[channelID][userID]; banned {
+		return "ban"
+	}
+	if expiry, ok := s.data.ChatTimeouts[channelID][userID]; ok && expiry.After(now) {
+		return "timeout"
+	}
+	return ""
+}
+
+// CreateChatAppeal files an appeal by a banned or timed-out user. It
+// returns ErrNotChatRestricted when the user is under no restriction and
+// ErrChatAppealOpen while an earlier appeal in the channel is still open.
+func (s *Storage) CreateChatAppeal(channelID, userID, message string) (models.ChatAppeal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data.Channels[channelID]; !ok {
+		return models.ChatAppeal{}, notFound("channel", channelID)
+	}
+	if _, ok := s.data.Users[userID]; !ok {
+		return models.ChatAppeal{}, notFound("user", userID)
+	}
+	now := time.Now().UTC()
+	restriction := s.activeChatRestrictionLocked(channelID, userID, now)
+	if restriction == "" {
+		return models.ChatAppeal{}, ErrNotChatRestricted
+	}
+	for _, existing := range s.data.ChatAppeals {
+		if existing.ChannelID == channelID && existing.UserID == userID && existing.Status == ChatAppealStatusOpen {
+			return models.ChatAppeal{}, ErrChatAppealOpen
+		}
+	}
+	appeal, err := newChatAppeal(channelID, userID, restriction, message, now)
+	if err != nil {
+		return models.ChatAppeal{}, err
+	}
+	s.data.ChatAppeals[appeal.ID] = appeal
+	if err := s.persist(); err != nil {
+		delete(s.data.ChatAppeals, appeal.ID)
+		return models.ChatAppeal{}, err
+	}
+	return appeal, nil
+}
+
+// GetChatAppeal returns one of the channel's appeals.
+func (s *Storage) GetChatAppeal(channelID, appealID string) (models.ChatAppeal, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	appeal, ok := s.data.ChatAppeals[appealID]
+	if !ok || appeal.ChannelID != channelID {
+		return models.ChatAppeal{}, ErrChatAppealNotFound
+	}
+	return appeal, nil
+}
+
+// ListChatAppeals returns the channel's appeals newest first, only the open
+// ones unless includeResolved is set.
+func (s *Storage) ListChatAppeals(channelID string, includeResolved bool) ([]models.ChatAppeal, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.data.Channels[channelID]; !ok {
+		return nil, notFound("channel", channelID)
+	}
+	appeals := make([]models.ChatAppeal, 0)
+	for _, appeal := range s.data.ChatAppeals {
+		if appeal.ChannelID != channelID {
+			continue
+		}
+		if !includeResolved && appeal.Status != ChatAppealStatusOpen {
+			continue
+		}
+		appeals = append(appeals, appeal)
+	}
+	sortChatAppeals(appeals)
+	return appeals, nil
+}
+
+// LatestChatAppeal returns the user's most recent appeal in the channel.
+func (s *Storage) LatestChatAppeal(channelID, userID string) (models.ChatAppeal, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var (
+		latest models.ChatAppeal
+		found  bool
+	)
+	for _, appeal := range s.data.ChatAppeals {
+		if appeal.ChannelID != channelID || appeal.UserID != userID {
+			continue
+		}
+		if !found || appeal.CreatedAt.After(latest.CreatedAt) || (appeal.CreatedAt.Equal(latest.CreatedAt) && appeal.ID < latest.ID) {
+			latest, found = appeal, true
+		}
+	}
+	return latest, found
+}
+
+// ResolveChatAppeal closes an open appeal. It only records the decision;
+// callers lift the restriction of an accepted appeal through the chat
+// moderation path. It returns ErrChatAppealResolved for a closed appeal.
+func (s *Storage) ResolveChatAppeal(channelID, appealID, resolverID string, accept bool, note string) (models.ChatAppeal, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	appeal, ok := s.data.ChatAppeals[appealID]
+	if !ok || appeal.ChannelID != channelID {
+		return models.ChatAppeal{}, ErrChatAppealNotFound
+	}
+	if _, ok := s.data.Users[resolverID]; !ok {
+		return models.ChatAppeal{}, notFound("resolver", resolverID)
+	}
+	resolved, err := resolveChatAppeal(appeal, resolverID, accept, note, time.Now().UTC())
+	if err != nil {
+		return models.ChatAppeal{}, err
+	}
+	s.data.ChatAppeals[appealID] = resolved
+	if err := s.persist(); err != nil {
+		s.data.ChatAppeals[appealID] = appeal
+		return models.ChatAppeal{}, err
+	}
+	return resolved, nil
+}
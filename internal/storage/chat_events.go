@@ -2,6 +2,7 @@ package storage
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"log/slog"
 	"strings"
@@ -10,8 +11,56 @@ import (
 
 	"bitriver-live/internal/chat"
 	"bitriver-live/internal/models"
+	"bitriver-live/internal/observability/health"
+	"bitriver-live/internal/observability/metrics"
 )
 
+// ChatEventsError reports the events of an ApplyChatEvents batch that could
+// not be applied. Errs is parallel to the batch, with nil entries for the
+// events that were stored.
+type ChatEventsError struct {
+	Errs []error
+}
+
+func (e *ChatEventsError) Error() string {
+	var (
+		failed int
+		first  error
+	)
+	for _, err := range e.Errs {
+		if err == nil {
+			continue
+		}
+		if first == nil {
+			first = err
+		}
+		failed++
+	}
+	return fmt.Sprintf("%d of %d chat events failed: %v", failed, len(e.Errs), first)
+}
+
+// Unwrap returns the errors of the failed events.
+func (e *ChatEventsError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errs))
+	for _, err := range e.Errs {
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errs
+}
+
+// chatEventsResult returns nil when every event of a batch was applied and
+// a *ChatEventsError otherwise.
+func chatEventsResult(errs []error) error {
+	for _, err := range errs {
+		if err != nil {
+			return &ChatEventsError{Errs: errs}
+		}
+	}
+	return nil
+}
+
 // ApplyChatEvent mutates the in-memory dataset based on the supplied chat
 // event and persists the change to disk.
 func (s *Storage) ApplyChatEvent(evt chat.Event) error {
@@ -20,39 +69,110 @@ func (s *Storage) ApplyChatEvent(evt chat.Event) error {
 
 	s.ensureDatasetInitializedLocked()
 
+	changed, err := s.applyChatEventLocked(evt)
+	if err != nil || !changed {
+		return err
+	}
+	return s.persist()
+}
+
+// ApplyChatEvents applies events in order under one lock and writes the
+// dataset to disk once for the whole batch. When that write fails no event
+// is reported stored.
+func (s *Storage) ApplyChatEvents(ctx context.Context, events []chat.Event) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ensureDatasetInitializedLocked()
+
+	errs := make([]error, len(events))
+	changed := false
+	for i, evt := range events {
+		applied, err := s.applyChatEventLocked(evt)
+		errs[i] = err
+		changed = changed || applied
+	}
+	if changed {
+		if err := s.persist(); err != nil {
+			return err
+		}
+	}
+	return chatEventsResult(errs)
+}
+
+// applyChatEventLocked applies evt to the dataset and reports whether it
+// changed anything that needs persisting.
+func (s *Storage) applyChatEventLocked(evt chat.Event) (bool, error) {
 	switch evt.Type {
 	case chat.EventTypeMessage:
 		if evt.Message == nil {
-			return fmt.Errorf("message payload missing")
+			return false, fmt.Errorf("message payload missing")
 		}
 		message := models.ChatMessage{
-			ID:        evt.Message.ID,
-			ChannelID: evt.Message.ChannelID,
-			UserID:    evt.Message.UserID,
-			Content:   evt.Message.Content,
-			CreatedAt: evt.Message.CreatedAt.UTC(),
+			ID:              evt.Message.ID,
+			ChannelID:       evt.Message.ChannelID,
+			UserID:          evt.Message.UserID,
+			Content:         evt.Message.Content,
+			ClientMessageID: evt.Message.ClientMessageID,
+			Seq:             evt.Seq,
+			CreatedAt:       evt.Message.CreatedAt.UTC(),
 		}
 		if message.ID == "" || message.ChannelID == "" || message.UserID == "" {
-			return fmt.Errorf("invalid message event")
+			return false, fmt.Errorf("invalid message event")
+		}
+		now := time.Now().UTC()
+		if original, ok := s.chatMessageByClientIDLocked(message.ChannelID, message.UserID, message.ClientMessageID, now); ok && original.ID != message.ID {
+			// A retried submission that another gateway already accepted.
+			return false, nil
 		}
 		s.data.ChatMessages[message.ID] = message
+		s.indexChatClientMessageLocked(message, now)
 	case chat.EventTypeModeration:
 		if evt.Moderation == nil {
-			return fmt.Errorf("moderation payload missing")
+			return false, fmt.Errorf("moderation payload missing")
+		}
+		if evt.Moderation.Action == chat.ModerationActionDeleteMessage {
+			if !s.applyMessageDeletionLocked(*evt.Moderation, evt.Seq, evt.OccurredAt) {
+				return false, nil
+			}
+			break
 		}
 		s.applyModerationLocked(*evt.Moderation, evt.OccurredAt)
+		if action, ok := moderationActionFromEvent(*evt.Moderation, evt.OccurredAt); ok {
+			action.Seq = evt.Seq
+			if _, err := s.appendModerationActionLocked(action); err != nil {
+				return false, err
+			}
+		}
 	case chat.EventTypeReport:
 		if evt.Report == nil {
-			return fmt.Errorf("report payload missing")
+			return false, fmt.Errorf("report payload missing")
 		}
 		if err := s.applyReportLocked(*evt.Report); err != nil {
-			return err
-		}
+			return false, err
+		}
+	case chat.EventTypeGift:
+		// Gifted subscriptions are persisted before the event is emitted.
+		return false, nil
+	case chat.EventTypeMarker:
+		// Stream markers are persisted before the event is emitted.
+		return false, nil
+	case chat.EventTypePin:
+		// Chat pins are persisted before the event is emitted.
+		return false, nil
+	case chat.EventTypeModerator:
+		// Moderator grants are persisted before the event is emitted.
+		return false, nil
 	default:
-		return fmt.Errorf("unsupported chat event %q", evt.Type)
+		return false, fmt.Errorf("unsupported chat event %q", evt.Type)
 	}
-
-	return s.persist()
+	return true, nil
 }
 
 func (s *Storage) applyModerationLocked(evt chat.ModerationEvent, occurredAt time.Time) {
@@ -138,6 +258,28 @@ func (s *Storage) applyModerationLocked(evt chat.ModerationEvent, occurredAt tim
 	}
 }
 
+// applyMessageDeletionLocked removes the message a delete_message event names
+// and logs the deletion under the event's ID and sequence number. It reports
+// false when there is nothing to delete, for example on redelivery.
+func (s *Storage) applyMessageDeletionLocked(evt chat.ModerationEvent, seq int64, occurredAt time.Time) bool {
+	message, ok := s.data.ChatMessages[evt.MessageID]
+	if !ok || message.ChannelID != evt.ChannelID {
+		return false
+	}
+	deletedAt := occurredAt.UTC()
+	if deletedAt.IsZero() {
+		deletedAt = time.Now().UTC()
+	}
+	action := deletedMessageAction(message, evt.ActorID, deletedAt)
+	action.ID = evt.ID
+	action.Seq = seq
+	if _, err := s.appendModerationActionLocked(action); err != nil {
+		return false
+	}
+	delete(s.data.ChatMessages, message.ID)
+	return true
+}
+
 func (s *Storage) applyReportLocked(evt chat.ReportEvent) error {
 	if strings.TrimSpace(evt.ID) == "" {
 		return fmt.Errorf("report id missing")
@@ -159,7 +301,7 @@ func (s *Storage) applyReportLocked(evt chat.ReportEvent) error {
 	if report.Status == "" {
 		report.Status = ChatReportStatusOpen
 	}
-	s.data.ChatReports[report.ID] = report
+	s.data.ChatReports[report.ID] = withReportDefaults(report)
 	return nil
 }
 
@@ -263,13 +405,30 @@ func (s *Storage) ChatTimeout(channelID, userID string) (time.Time, bool) {
 	return s.chatTimeoutLocked(channelID, userID)
 }
 
-// ChatWorker consumes queue events and applies them to storage.
+// errChatSubscriptionClosed marks the chat worker degraded when the queue
+// stops delivering events before the worker is cancelled.
+var errChatSubscriptionClosed = errors.New("chat queue subscription closed")
+
+const (
+	// DefaultChatBatchSize is the most events the chat worker writes to
+	// storage at once.
+	DefaultChatBatchSize = 100
+	// DefaultChatBatchWait is how long the chat worker waits for a batch to
+	// fill once its first event arrives.
+	DefaultChatBatchWait = 25 * time.Millisecond
+)
+
+// ChatWorker consumes queue events and applies them to storage in batches.
 type ChatWorker struct {
 	queue       chat.Queue
 	store       Repository
 	logger      *slog.Logger
 	started     chan struct{}
 	startedOnce sync.Once
+	health      *health.Component
+	batchSize   int
+	batchWait   time.Duration
+	metrics     *metrics.Recorder
 }
 
 // NewChatWorker prepares a worker that will persist chat events delivered via the queue.
@@ -277,7 +436,7 @@ func NewChatWorker(store Repository, queue chat.Queue, logger *slog.Logger) *Cha
 	if logger == nil {
 		logger = slog.Default()
 	}
-	return &ChatWorker{queue: queue, store: store, logger: logger}
+	return &ChatWorker{queue: queue, store: store, logger: logger, batchSize: DefaultChatBatchSize, batchWait: DefaultChatBatchWait}
 }
 
 // WithStartedChannel signals when the worker has begun consuming events. It should be set
@@ -287,6 +446,40 @@ func (w *ChatWorker) WithStartedChannel(started chan struct{}) *ChatWorker {
 	return w
 }
 
+// WithHealth reports the worker's heartbeats and failures to component. It
+// should be set before calling Run.
+func (w *ChatWorker) WithHealth(component *health.Component) *ChatWorker {
+	w.health = component
+	return w
+}
+
+// WithBatching sets the most events written at once and how long the worker
+// waits for a batch to fill. A size of 1 writes every event as it arrives;
+// zero values keep the defaults. It should be set before calling Run.
+func (w *ChatWorker) WithBatching(size int, wait time.Duration) *ChatWorker {
+	if size > 0 {
+		w.batchSize = size
+	}
+	if wait > 0 {
+		w.batchWait = wait
+	}
+	return w
+}
+
+// WithMetrics records batch sizes and flush latency on recorder instead of
+// the default recorder. It should be set before calling Run.
+func (w *ChatWorker) WithMetrics(recorder *metrics.Recorder) *ChatWorker {
+	w.metrics = recorder
+	return w
+}
+
+func (w *ChatWorker) recorder() *metrics.Recorder {
+	if w.metrics != nil {
+		return w.metrics
+	}
+	return metrics.Default()
+}
+
 func (w *ChatWorker) notifyStarted() {
 	if w.started == nil {
 		return
@@ -297,24 +490,170 @@ func (w *ChatWorker) notifyStarted() {
 }
 
 // Run blocks until the context is cancelled, persisting chat events as they arrive.
+// Reliable queues acknowledge each event once it is stored and report failures
+// back so poison events are retried and eventually dead-lettered.
 func (w *ChatWorker) Run(ctx context.Context) {
 	if w.queue == nil || w.store == nil {
 		return
 	}
+	if reliable, ok := w.queue.(chat.ReliableQueue); ok {
+		w.consume(ctx, reliable)
+		return
+	}
 	sub := w.queue.Subscribe()
 	defer sub.Close()
+	heartbeat := time.NewTicker(w.health.HeartbeatInterval())
+	defer heartbeat.Stop()
+	w.health.SetHealthy()
 	w.notifyStarted()
 	for {
 		select {
 		case <-ctx.Done():
 			return
+		case <-heartbeat.C:
+			w.health.Heartbeat()
 		case evt, ok := <-sub.Events():
 			if !ok {
+				w.health.SetDegraded(errChatSubscriptionClosed)
 				return
 			}
-			if err := w.store.ApplyChatEvent(evt); err != nil && w.logger != nil {
-				w.logger.Error("failed to apply chat event", "error", err)
+			batch, open := collectChatBatch(ctx, evt, sub.Events(), w.batchSize, w.batchWait)
+			w.applyEvents(ctx, batch)
+			if !open {
+				w.health.SetDegraded(errChatSubscriptionClosed)
+				return
+			}
+		}
+	}
+}
+
+func (w *ChatWorker) consume(ctx context.Context, queue chat.ReliableQueue) {
+	sub := queue.Consume()
+	defer sub.Close()
+	heartbeat := time.NewTicker(w.health.HeartbeatInterval())
+	defer heartbeat.Stop()
+	w.health.SetHealthy()
+	w.notifyStarted()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-heartbeat.C:
+			w.health.Heartbeat()
+		case delivery, ok := <-sub.Deliveries():
+			if !ok {
+				w.health.SetDegraded(errChatSubscriptionClosed)
+				return
+			}
+			batch, open := collectChatBatch(ctx, delivery, sub.Deliveries(), w.batchSize, w.batchWait)
+			w.applyDeliveries(ctx, batch)
+			if !open {
+				w.health.SetDegraded(errChatSubscriptionClosed)
+				return
+			}
+		}
+	}
+}
+
+// collectChatBatch gathers first and whatever else arrives on ch until the
+// batch holds size items or wait has passed. It reports false when ch was
+// closed while the batch was filling.
+func collectChatBatch[T any](ctx context.Context, first T, ch <-chan T, size int, wait time.Duration) ([]T, bool) {
+	batch := []T{first}
+	if size <= 1 {
+		return batch, true
+	}
+	timer := time.NewTimer(wait)
+	defer timer.Stop()
+	for len(batch) < size {
+		select {
+		case item, ok := <-ch:
+			if !ok {
+				return batch, false
+			}
+			batch = append(batch, item)
+		case <-timer.C:
+			return batch, true
+		case <-ctx.Done():
+			return batch, true
+		}
+	}
+	return batch, true
+}
+
+// writeChatBatch stores events and returns each event's failure, or nil
+// when every event was stored.
+func (w *ChatWorker) writeChatBatch(ctx context.Context, events []chat.Event) []error {
+	started := time.Now()
+	err := w.store.ApplyChatEvents(ctx, events)
+	var errs []error
+	if err != nil {
+		var batchErr *ChatEventsError
+		if errors.As(err, &batchErr) && len(batchErr.Errs) == len(events) {
+			errs = batchErr.Errs
+		} else {
+			errs = make([]error, len(events))
+			for i := range errs {
+				errs[i] = err
 			}
 		}
 	}
+	failed := 0
+	for _, err := range errs {
+		if err != nil {
+			failed++
+		}
+	}
+	w.recorder().ObserveChatBatch(len(events), failed, time.Since(started))
+	return errs
+}
+
+func (w *ChatWorker) applyEvents(ctx context.Context, events []chat.Event) {
+	errs := w.writeChatBatch(ctx, events)
+	healthy := true
+	for i, err := range errs {
+		if err == nil {
+			continue
+		}
+		healthy = false
+		w.health.SetDegraded(err)
+		if w.logger != nil {
+			w.logger.Error("failed to apply chat event", "type", events[i].Type, "error", err)
+		}
+	}
+	if healthy {
+		w.health.SetHealthy()
+	}
+}
+
+func (w *ChatWorker) applyDeliveries(ctx context.Context, deliveries []chat.Delivery) {
+	events := make([]chat.Event, len(deliveries))
+	for i, delivery := range deliveries {
+		events[i] = delivery.Event
+	}
+	errs := w.writeChatBatch(ctx, events)
+	healthy := true
+	for i, delivery := range deliveries {
+		if errs != nil && errs[i] != nil {
+			healthy = false
+			w.health.SetDegraded(errs[i])
+			if w.logger != nil {
+				w.logger.Error("failed to apply chat event", "id", delivery.ID, "attempt", delivery.Attempt, "error", errs[i])
+			}
+			if err := delivery.Fail(errs[i]); err != nil && w.logger != nil {
+				w.logger.Error("failed to report chat event failure", "id", delivery.ID, "error", err)
+			}
+			continue
+		}
+		if err := delivery.Ack(); err != nil {
+			healthy = false
+			w.health.SetDegraded(err)
+			if w.logger != nil {
+				w.logger.Error("failed to acknowledge chat event", "id", delivery.ID, "error", err)
+			}
+		}
+	}
+	if healthy {
+		w.health.SetHealthy()
+	}
 }
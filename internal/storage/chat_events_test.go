@@ -3,10 +3,12 @@ package storage
 import (
 	"context"
 	"errors"
+	"sync"
 	"testing"
 	"time"
 
 	"bitriver-live/internal/chat"
+	"bitriver-live/internal/observability/metrics"
 )
 
 func TestApplyChatEventPersistsMessage(t *testing.T) {
@@ -51,6 +53,63 @@ func TestApplyChatEventPersistsMessage(t *testing.T) {
 	}
 }
 
+func TestApplyChatEventDeduplicatesClientMessageIDs(t *testing.T) {
+	store := newTestStore(t)
+	user, err := store.CreateUser(CreateUserParams{DisplayName: "viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(user.ID, "Lobby", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	const clientID = "0b8f5a2e-3c41-4d6f-8a9b-1c2d3e4f5a6b"
+	messageEvent := func(id, content string) chat.Event {
+		return chat.Event{
+			Type: chat.EventTypeMessage,
+			Message: &chat.MessageEvent{
+				ID:              id,
+				ChannelID:       channel.ID,
+				UserID:          user.ID,
+				Content:         content,
+				ClientMessageID: clientID,
+				CreatedAt:       time.Now().UTC(),
+			},
+			OccurredAt: time.Now().UTC(),
+		}
+	}
+
+	for _, evt := range []chat.Event{
+		messageEvent("msg-1", "hello"),
+		messageEvent("msg-1", "hello"),
+		messageEvent("msg-2", "hello"),
+	} {
+		if err := store.ApplyChatEvent(evt); err != nil {
+			t.Fatalf("ApplyChatEvent(%s): %v", evt.Message.ID, err)
+		}
+	}
+	messages, err := store.ListChatMessages(channel.ID, 0)
+	if err != nil {
+		t.Fatalf("ListChatMessages: %v", err)
+	}
+	if len(messages) != 1 || messages[0].ID != "msg-1" || messages[0].ClientMessageID != clientID {
+		t.Fatalf("expected the replayed submission to be stored once, got %+v", messages)
+	}
+
+	// The index is rebuilt from disk, so a restart does not forget the ID.
+	reopened, err := NewStorage(store.filePath)
+	if err != nil {
+		t.Fatalf("NewStorage: %v", err)
+	}
+	retried, err := reopened.CreateChatMessage(channel.ID, user.ID, "hello", clientID)
+	if err != nil {
+		t.Fatalf("CreateChatMessage: %v", err)
+	}
+	if retried.ID != "msg-1" {
+		t.Fatalf("expected the retry after a restart to return msg-1, got %s", retried.ID)
+	}
+}
+
 func TestChatRestrictionsReflectModeration(t *testing.T) {
 	RunRepositoryChatRestrictionsLifecycle(t, jsonRepositoryFactory)
 }
@@ -297,3 +356,311 @@ func (s *recordingStore) ApplyChatEvent(evt chat.Event) error {
 	}
 	return s.Repository.ApplyChatEvent(evt)
 }
+
+// ApplyChatEvents signals each event only once the batch has been written,
+// so tests waiting on applied can read the results straight away.
+func (s *recordingStore) ApplyChatEvents(ctx context.Context, events []chat.Event) error {
+	err := s.applyErr
+	if err == nil {
+		err = s.Repository.ApplyChatEvents(ctx, events)
+	}
+	if s.applied != nil {
+		for _, evt := range events {
+			s.applied <- evt
+		}
+	}
+	return err
+}
+
+func TestChatWorkerDeadLettersPoisonEventAndRequeues(t *testing.T) {
+	store := &flakyApplyStore{Repository: newTestStore(t)}
+	store.setErr(errors.New("cannot persist"))
+	owner, err := store.CreateUser(CreateUserParams{DisplayName: "owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser owner: %v", err)
+	}
+	viewer, err := store.CreateUser(CreateUserParams{DisplayName: "viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser viewer: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Lobby", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+
+	recorder := metrics.New()
+	queue := chat.NewMemoryQueueWithConfig(chat.MemoryQueueConfig{
+		MaxDeliveries: 3,
+		RetryDelay:    5 * time.Millisecond,
+		Metrics:       recorder,
+	})
+	started := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go NewChatWorker(store, queue, nil).WithStartedChannel(started).Run(ctx)
+	waitForSignal(t, started)
+
+	messageEvt := chat.Event{
+		Type: chat.EventTypeMessage,
+		Message: &chat.MessageEvent{
+			ID:        "evt-poison",
+			ChannelID: channel.ID,
+			UserID:    viewer.ID,
+			Content:   "hello",
+			CreatedAt: time.Now().UTC(),
+		},
+		OccurredAt: time.Now().UTC(),
+	}
+	if err := queue.Publish(ctx, messageEvt); err != nil {
+		t.Fatalf("Publish message: %v", err)
+	}
+
+	var letters []chat.DeadLetter
+	deadline := time.Now().Add(2 * time.Second)
+	for len(letters) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for dead letter")
+		}
+		time.Sleep(5 * time.Millisecond)
+		if letters, err = queue.ListDeadLetters(ctx, 0); err != nil {
+			t.Fatalf("ListDeadLetters: %v", err)
+		}
+	}
+	if attempts := store.attempts(); attempts != 3 {
+		t.Fatalf("expected 3 apply attempts before dead-lettering, got %d", attempts)
+	}
+	if letters[0].Error != "cannot persist" {
+		t.Fatalf("expected apply error on dead letter, got %q", letters[0].Error)
+	}
+	if _, pending, dead := recorder.ChatQueueStats(); pending != 0 || dead != 1 {
+		t.Fatalf("expected metrics pending=0 dead=1, got pending=%d dead=%d", pending, dead)
+	}
+
+	store.setErr(nil)
+	if err := queue.RequeueDeadLetter(ctx, letters[0].ID); err != nil {
+		t.Fatalf("RequeueDeadLetter: %v", err)
+	}
+	deadline = time.Now().Add(2 * time.Second)
+	for {
+		messages, err := store.ListChatMessages(channel.ID, 0)
+		if err != nil {
+			t.Fatalf("ListChatMessages: %v", err)
+		}
+		if len(messages) == 1 && messages[0].ID == messageEvt.Message.ID {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("timeout waiting for requeued message, got %d messages", len(messages))
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	stats, err := queue.Stats(ctx)
+	if err != nil {
+		t.Fatalf("Stats: %v", err)
+	}
+	if stats.DeadLetters != 0 {
+		t.Fatalf("expected empty dead-letter queue, got %+v", stats)
+	}
+}
+
+type flakyApplyStore struct {
+	Repository
+	mu       sync.Mutex
+	applyErr error
+	calls    int
+}
+
+func (s *flakyApplyStore) setErr(err error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.applyErr = err
+}
+
+func (s *flakyApplyStore) attempts() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.calls
+}
+
+func (s *flakyApplyStore) ApplyChatEvent(evt chat.Event) error {
+	s.mu.Lock()
+	s.calls++
+	err := s.applyErr
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.Repository.ApplyChatEvent(evt)
+}
+
+func (s *flakyApplyStore) ApplyChatEvents(ctx context.Context, events []chat.Event) error {
+	s.mu.Lock()
+	s.calls += len(events)
+	err := s.applyErr
+	s.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	return s.Repository.ApplyChatEvents(ctx, events)
+}
+
+func TestChatWorkerBatchesEventsInOrder(t *testing.T) {
+	store := &batchCountingStore{Repository: newTestStore(t)}
+	owner, err := store.CreateUser(CreateUserParams{DisplayName: "owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser owner: %v", err)
+	}
+	viewer, err := store.CreateUser(CreateUserParams{DisplayName: "viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser viewer: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Lobby", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+
+	now := time.Now().UTC()
+	events := []chat.Event{
+		{Type: chat.EventTypeMessage, Seq: 1, Message: &chat.MessageEvent{ID: "msg-1", ChannelID: channel.ID, UserID: viewer.ID, Content: "first", CreatedAt: now}, OccurredAt: now},
+		{Type: chat.EventTypeMessage, Seq: 2, Message: &chat.MessageEvent{ID: "msg-2", ChannelID: channel.ID, UserID: viewer.ID, Content: "second", CreatedAt: now}, OccurredAt: now},
+		{Type: chat.EventTypeModeration, Seq: 3, Moderation: &chat.ModerationEvent{ID: "mod-1", Action: chat.ModerationActionDeleteMessage, ChannelID: channel.ID, ActorID: owner.ID, TargetID: viewer.ID, MessageID: "msg-1"}, OccurredAt: now},
+	}
+	queue := newRecordingQueue()
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	for _, evt := range events {
+		if err := queue.Publish(ctx, evt); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+
+	recorder := metrics.New()
+	started := make(chan struct{})
+	worker := NewChatWorker(store, queue, nil).WithStartedChannel(started).WithBatching(10, 20*time.Millisecond).WithMetrics(recorder)
+	go worker.Run(ctx)
+	waitForSignal(t, started)
+
+	deadline := time.Now().Add(time.Second)
+	for store.batchCount() == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for batch flush")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+
+	messages, err := store.ListChatMessages(channel.ID, 0)
+	if err != nil {
+		t.Fatalf("ListChatMessages: %v", err)
+	}
+	if len(messages) != 1 || messages[0].ID != "msg-2" {
+		t.Fatalf("expected only msg-2 to remain after the in-batch delete, got %+v", messages)
+	}
+	if sizes, single := store.stats(); len(sizes) != 1 || sizes[0] != len(events) || single != 0 {
+		t.Fatalf("expected one batch of %d events and no single writes, got batches %v and %d single writes", len(events), sizes, single)
+	}
+	if batches, count, failed, _ := recorder.ChatBatchStats(); batches != 1 || count != uint64(len(events)) || failed != 0 {
+		t.Fatalf("unexpected batch metrics: batches=%d events=%d failed=%d", batches, count, failed)
+	}
+}
+
+func TestChatWorkerFailsOnlyBadDeliveriesInBatch(t *testing.T) {
+	store := newTestStore(t)
+	owner, err := store.CreateUser(CreateUserParams{DisplayName: "owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser owner: %v", err)
+	}
+	viewer, err := store.CreateUser(CreateUserParams{DisplayName: "viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser viewer: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Lobby", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+
+	recorder := metrics.New()
+	queue := chat.NewMemoryQueueWithConfig(chat.MemoryQueueConfig{
+		MaxDeliveries: 1,
+		RetryDelay:    5 * time.Millisecond,
+		Metrics:       recorder,
+	})
+	started := make(chan struct{})
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go NewChatWorker(store, queue, nil).WithStartedChannel(started).WithBatching(3, time.Second).WithMetrics(recorder).Run(ctx)
+	waitForSignal(t, started)
+
+	now := time.Now().UTC()
+	events := []chat.Event{
+		{Type: chat.EventTypeMessage, Message: &chat.MessageEvent{ID: "good-1", ChannelID: channel.ID, UserID: viewer.ID, Content: "one", CreatedAt: now}, OccurredAt: now},
+		{Type: chat.EventTypeMessage, Message: &chat.MessageEvent{ID: "bad", ChannelID: channel.ID, Content: "no author", CreatedAt: now}, OccurredAt: now},
+		{Type: chat.EventTypeMessage, Message: &chat.MessageEvent{ID: "good-2", ChannelID: channel.ID, UserID: viewer.ID, Content: "two", CreatedAt: now}, OccurredAt: now},
+	}
+	for _, evt := range events {
+		if err := queue.Publish(ctx, evt); err != nil {
+			t.Fatalf("Publish: %v", err)
+		}
+	}
+
+	var letters []chat.DeadLetter
+	deadline := time.Now().Add(2 * time.Second)
+	for len(letters) == 0 {
+		if time.Now().After(deadline) {
+			t.Fatal("timeout waiting for dead letter")
+		}
+		time.Sleep(5 * time.Millisecond)
+		if letters, err = queue.ListDeadLetters(ctx, 0); err != nil {
+			t.Fatalf("ListDeadLetters: %v", err)
+		}
+	}
+	if len(letters) != 1 || letters[0].Event.Message == nil || letters[0].Event.Message.ID != "bad" {
+		t.Fatalf("expected only the invalid event to be dead-lettered, got %+v", letters)
+	}
+	messages, err := store.ListChatMessages(channel.ID, 0)
+	if err != nil {
+		t.Fatalf("ListChatMessages: %v", err)
+	}
+	if len(messages) != 2 {
+		t.Fatalf("expected the two valid messages to be stored, got %+v", messages)
+	}
+	if _, pending, dead := recorder.ChatQueueStats(); pending != 0 || dead != 1 {
+		t.Fatalf("expected metrics pending=0 dead=1, got pending=%d dead=%d", pending, dead)
+	}
+	if batches, count, failed, _ := recorder.ChatBatchStats(); batches != 1 || count != 3 || failed != 1 {
+		t.Fatalf("unexpected batch metrics: batches=%d events=%d failed=%d", batches, count, failed)
+	}
+}
+
+type batchCountingStore struct {
+	Repository
+	mu      sync.Mutex
+	batches []int
+	single  int
+}
+
+func (s *batchCountingStore) ApplyChatEvent(evt chat.Event) error {
+	s.mu.Lock()
+	s.single++
+	s.mu.Unlock()
+	return s.Repository.ApplyChatEvent(evt)
+}
+
+func (s *batchCountingStore) ApplyChatEvents(ctx context.Context, events []chat.Event) error {
+	err := s.Repository.ApplyChatEvents(ctx, events)
+	s.mu.Lock()
+	s.batches = append(s.batches, len(events))
+	s.mu.Unlock()
+	return err
+}
+
+func (s *batchCountingStore) batchCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.batches)
+}
+
+func (s *batchCountingStore) stats() ([]int, int) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]int(nil), s.batches...), s.single
+}
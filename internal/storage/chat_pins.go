@@ -0,0 +1,125 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"bitriver-live/internal/chat"
+	"bitriver-live/internal/models"
+)
+
+// normalizeChatPinParams validates params. Free text is processed the way
+// chat messages are, without the caps rule, and held to MaxChatMessageLength.
+func normalizeChatPinParams(params ChatPinParams) (ChatPinParams, error) {
+	params.ActorID = strings.TrimSpace(params.ActorID)
+	params.MessageID = strings.TrimSpace(params.MessageID)
+	if params.ActorID == "" {
+		return ChatPinParams{}, fmt.Errorf("actor id is required")
+	}
+	hasText := strings.TrimSpace(params.Content) != ""
+	if params.MessageID == "" && !hasText {
+		return ChatPinParams{}, errors.New("messageId or content is required")
+	}
+	if params.MessageID != "" && hasText {
+		return ChatPinParams{}, errors.New("messageId and content are mutually exclusive")
+	}
+	if params.MessageID != "" {
+		params.Content = ""
+		return params, nil
+	}
+	params.Content = chat.ProcessContent(params.Content, 0).Content
+	if params.Content == "" {
+		return ChatPinParams{}, errors.New("pin content cannot be empty")
+	}
+	if utf8.RuneCountInString(params.Content) > MaxChatMessageLength {
+		return ChatPinParams{}, fmt.Errorf("pin content exceeds %d characters", MaxChatMessageLength)
+	}
+	return params, nil
+}
+
+// newChatPin builds the pin for params. message is the pinned message when
+// params names one and is copied so the pin survives its deletion.
+func newChatPin(channelID string, params ChatPinParams, message *models.ChatMessage, now time.Time) models.ChatPin {
+	pin := models.ChatPin{
+		ChannelID: channelID,
+		Content:   params.Content,
+		PinnedBy:  params.ActorID,
+		PinnedAt:  now.UTC(),
+	}
+	if message != nil {
+		pin.MessageID = message.ID
+		pin.AuthorID = message.UserID
+		pin.Content = message.Content
+	}
+	return pin
+}
+
+// PinChatMessage pins a message above the channel's chat, replacing any
+// message already pinned there.
+func (s *Storage) PinChatMessage(channelID string, params ChatPinParams) (models.ChatPin, error) {
+	params, err := normalizeChatPinParams(params)
+	if err != nil {
+		return models.ChatPin{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data.Channels[channelID]; !ok {
+		return models.ChatPin{}, notFound("channel", channelID)
+	}
+	if _, ok := s.data.Users[params.ActorID]; !ok {
+		return models.ChatPin{}, notFound("user", params.ActorID)
+	}
+	var message *models.ChatMessage
+	if params.MessageID != "" {
+		found, ok := s.data.ChatMessages[params.MessageID]
+		if !ok || found.ChannelID != channelID {
+			return models.ChatPin{}, notFound("chat message", params.MessageID)
+		}
+		message = &found
+	}
+
+	pin := newChatPin(channelID, params, message, time.Now())
+	previous, hadPin := s.data.ChatPins[channelID]
+	s.data.ChatPins[channelID] = pin
+	if err := s.persist(); err != nil {
+		if hadPin {
+			s.data.ChatPins[channelID] = previous
+		} else {
+			delete(s.data.ChatPins, channelID)
+		}
+		return models.ChatPin{}, err
+	}
+	return pin, nil
+}
+
+// UnpinChatMessage removes the channel's pinned message and returns it. It
+// returns ErrChatPinNotFound when nothing is pinned.
+func (s *Storage) UnpinChatMessage(channelID string) (models.ChatPin, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data.Channels[channelID]; !ok {
+		return models.ChatPin{}, notFound("channel", channelID)
+	}
+	pin, ok := s.data.ChatPins[channelID]
+	if !ok {
+		return models.ChatPin{}, ErrChatPinNotFound
+	}
+	delete(s.data.ChatPins, channelID)
+	if err := s.persist(); err != nil {
+		s.data.ChatPins[channelID] = pin
+		return models.ChatPin{}, err
+	}
+	return pin, nil
+}
+
+// ChatPin returns the channel's pinned message, if any.
+func (s *Storage) ChatPin(channelID string) (models.ChatPin, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	pin, ok := s.data.ChatPins[channelID]
+	return pin, ok
+}
@@ -0,0 +1,121 @@
+package storage
+
+import (
+	"sort"
+	"time"
+
+	"bitriver-live/internal/chat"
+	"bitriver-live/internal/models"
+)
+
+// chatEventFromMessage rebuilds the chat event a stored message was
+// persisted from, without the author view.
+func chatEventFromMessage(message models.ChatMessage) chat.Event {
+	return chat.Event{
+		Type: chat.EventTypeMessage,
+		Seq:  message.Seq,
+		Message: &chat.MessageEvent{
+			ID:              message.ID,
+			ChannelID:       message.ChannelID,
+			UserID:          message.UserID,
+			Content:         message.Content,
+			URLs:            chat.ExtractURLs(message.Content),
+			ClientMessageID: message.ClientMessageID,
+			CreatedAt:       message.CreatedAt,
+		},
+		OccurredAt: message.CreatedAt,
+	}
+}
+
+// chatEventFromModerationAction rebuilds the moderation event a log entry
+// was written for. It returns false for entries no chat moderation event
+// produces, such as resolved reports.
+func chatEventFromModerationAction(action models.ModerationAction) (chat.Event, bool) {
+	moderation := chat.ModerationEvent{
+		ID:        action.ID,
+		ChannelID: action.ChannelID,
+		ActorID:   action.ActorID,
+		TargetID:  action.TargetID,
+		Reason:    action.Reason,
+	}
+	switch action.Action {
+	case models.ModerationActionBan:
+		moderation.Action = chat.ModerationActionBan
+	case models.ModerationActionUnban:
+		moderation.Action = chat.ModerationActionUnban
+	case models.ModerationActionTimeout:
+		moderation.Action = chat.ModerationActionTimeout
+		if expires, err := time.Parse(time.RFC3339Nano, action.Metadata["expiresAt"]); err == nil {
+			expires = expires.UTC()
+			moderation.ExpiresAt = &expires
+		}
+	case models.ModerationActionRemoveTimeout:
+		moderation.Action = chat.ModerationActionRemoveTimeout
+	case models.ModerationActionDeleteMessage:
+		moderation.Action = chat.ModerationActionDeleteMessage
+		moderation.MessageID = action.Metadata["messageId"]
+	default:
+		return chat.Event{}, false
+	}
+	return chat.Event{Type: chat.EventTypeModeration, Seq: action.Seq, Moderation: &moderation, OccurredAt: action.CreatedAt}, true
+}
+
+// sortChatEventsBySeq orders events by sequence number and keeps the first
+// limit of them when limit is positive.
+func sortChatEventsBySeq(events []chat.Event, limit int) []chat.Event {
+	sort.Slice(events, func(i, j int) bool { return events[i].Seq < events[j].Seq })
+	if limit > 0 && len(events) > limit {
+		events = events[:limit]
+	}
+	return events
+}
+
+// NextChatSequence increments the channel's chat sequence counter and
+// persists it before returning the new value.
+func (s *Storage) NextChatSequence(channelID string) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.ensureDatasetInitializedLocked()
+	if _, ok := s.data.Channels[channelID]; !ok {
+		return 0, notFound("channel", channelID)
+	}
+	previous, had := s.data.ChatSequences[channelID]
+	next := previous + 1
+	s.data.ChatSequences[channelID] = next
+	if err := s.persist(); err != nil {
+		if had {
+			s.data.ChatSequences[channelID] = previous
+		} else {
+			delete(s.data.ChatSequences, channelID)
+		}
+		return 0, err
+	}
+	return next, nil
+}
+
+// ChatEventsSince rebuilds the channel's stored messages and moderation log
+// entries numbered above afterSeq, in sequence order.
+func (s *Storage) ChatEventsSince(channelID string, afterSeq int64, limit int) ([]chat.Event, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.data.Channels[channelID]; !ok {
+		return nil, notFound("channel", channelID)
+	}
+	events := make([]chat.Event, 0)
+	for _, message := range s.data.ChatMessages {
+		if message.ChannelID == channelID && message.Seq > afterSeq {
+			events = append(events, chatEventFromMessage(message))
+		}
+	}
+	for _, action := range s.data.ModerationActions {
+		if action.ChannelID != channelID || action.Seq <= afterSeq {
+			continue
+		}
+		if evt, ok := chatEventFromModerationAction(cloneModerationAction(action)); ok {
+			events = append(events, evt)
+		}
+	}
+	return sortChatEventsBySeq(events, limit), nil
+}
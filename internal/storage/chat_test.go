@@ -1,9 +1,14 @@
 package storage
 
 import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 	"time"
 
+	"bitriver-live/internal/chat"
 	"bitriver-live/internal/ingest"
 	"bitriver-live/internal/models"
 )
@@ -22,11 +27,11 @@ func TestListChatMessagesOrdering(t *testing.T) {
 		t.Fatalf("CreateChannel: %v", err)
 	}
 
-	msg1, err := store.CreateChatMessage(channel.ID, user.ID, "first")
+	msg1, err := store.CreateChatMessage(channel.ID, user.ID, "first", "")
 	if err != nil {
 		t.Fatalf("CreateChatMessage #1: %v", err)
 	}
-	msg2, err := store.CreateChatMessage(channel.ID, user.ID, "second")
+	msg2, err := store.CreateChatMessage(channel.ID, user.ID, "second", "")
 	if err != nil {
 		t.Fatalf("CreateChatMessage #2: %v", err)
 	}
@@ -46,6 +51,67 @@ func TestListChatMessagesOrdering(t *testing.T) {
 	}
 }
 
+func TestCreateChatMessageDeduplicatesClientMessageIDs(t *testing.T) {
+	store := newTestStore(t)
+	alice, err := store.CreateUser(CreateUserParams{DisplayName: "Alice", Email: "alice@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	bob, err := store.CreateUser(CreateUserParams{DisplayName: "Bob", Email: "bob@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(alice.ID, "My Channel", "", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	const clientID = "6F1C2A4E-8B7D-4C3A-9E21-0D5B6A7C8E9F"
+
+	if _, err := store.CreateChatMessage(channel.ID, alice.ID, "hello", "not-a-uuid"); !errors.Is(err, chat.ErrInvalidClientMessageID) {
+		t.Fatalf("expected an invalid client message id to be rejected, got %v", err)
+	}
+
+	original, err := store.CreateChatMessage(channel.ID, alice.ID, "hello", clientID)
+	if err != nil {
+		t.Fatalf("CreateChatMessage: %v", err)
+	}
+	if original.ClientMessageID != strings.ToLower(clientID) {
+		t.Fatalf("expected the canonical client message id, got %q", original.ClientMessageID)
+	}
+	retried, err := store.CreateChatMessage(channel.ID, alice.ID, "hello again", clientID)
+	if err != nil {
+		t.Fatalf("CreateChatMessage retry: %v", err)
+	}
+	if retried.ID != original.ID || retried.Content != "hello" {
+		t.Fatalf("expected the retry to return the original message, got %+v", retried)
+	}
+
+	other, err := store.CreateChatMessage(channel.ID, bob.ID, "hi", clientID)
+	if err != nil {
+		t.Fatalf("CreateChatMessage for another user: %v", err)
+	}
+	if other.ID == original.ID {
+		t.Fatal("expected another user's message with the same client id to be stored separately")
+	}
+	if msgs, err := store.ListChatMessages(channel.ID, 0); err != nil || len(msgs) != 2 {
+		t.Fatalf("expected 2 messages, got %d (%v)", len(msgs), err)
+	}
+
+	// Once the window has passed the ID may be used again.
+	store.mu.Lock()
+	aged := store.data.ChatMessages[original.ID]
+	aged.CreatedAt = aged.CreatedAt.Add(-chat.ClientMessageIDWindow)
+	store.data.ChatMessages[original.ID] = aged
+	store.mu.Unlock()
+	reused, err := store.CreateChatMessage(channel.ID, alice.ID, "hello later", clientID)
+	if err != nil {
+		t.Fatalf("CreateChatMessage after the window: %v", err)
+	}
+	if reused.ID == original.ID || reused.Content != "hello later" {
+		t.Fatalf("expected a new message after the window, got %+v", reused)
+	}
+}
+
 func TestDeleteChatMessage(t *testing.T) {
 	store := newTestStore(t)
 	user, err := store.CreateUser(CreateUserParams{
@@ -60,15 +126,15 @@ func TestDeleteChatMessage(t *testing.T) {
 		t.Fatalf("CreateChannel: %v", err)
 	}
 
-	msg, err := store.CreateChatMessage(channel.ID, user.ID, "hello")
+	msg, err := store.CreateChatMessage(channel.ID, user.ID, "hello", "")
 	if err != nil {
 		t.Fatalf("CreateChatMessage: %v", err)
 	}
 
-	if err := store.DeleteChatMessage(channel.ID, msg.ID); err != nil {
+	if err := store.DeleteChatMessage(channel.ID, msg.ID, user.ID); err != nil {
 		t.Fatalf("DeleteChatMessage: %v", err)
 	}
-	if err := store.DeleteChatMessage(channel.ID, msg.ID); err != nil {
+	if err := store.DeleteChatMessage(channel.ID, msg.ID, user.ID); err != nil {
 		t.Fatalf("expected deleting missing message to be a no-op, got %v", err)
 	}
 }
@@ -101,7 +167,10 @@ func TestListFollowedChannelIDsOrdersByRecency(t *testing.T) {
 		t.Fatalf("FollowChannel beta: %v", err)
 	}
 
-	followed := store.ListFollowedChannelIDs(viewer.ID)
+	followed, err := store.ListFollowedChannelIDs(viewer.ID)
+	if err != nil {
+		t.Fatalf("ListFollowedChannelIDs: %v", err)
+	}
 	if len(followed) != 2 || followed[0] != second.ID || followed[1] != first.ID {
 		t.Fatalf("expected channels ordered by recency, got %v", followed)
 	}
@@ -146,7 +215,10 @@ func TestListChatRestrictionsSkipsExpiredTimeouts(t *testing.T) {
 	store.data.ChatTimeoutReasons[channel.ID][expired.ID] = "expired"
 	store.mu.Unlock()
 
-	restrictions := store.ListChatRestrictions(channel.ID)
+	restrictions, err := store.ListChatRestrictions(channel.ID)
+	if err != nil {
+		t.Fatalf("ListChatRestrictions: %v", err)
+	}
 	if len(restrictions) != 1 {
 		t.Fatalf("expected 1 restriction, got %d", len(restrictions))
 	}
@@ -210,7 +282,7 @@ func TestExpiredTimeoutsClearedAndPersisted(t *testing.T) {
 	store.data.ChatTimeoutReasons[channel.ID][viewer.ID] = "expired"
 	store.mu.Unlock()
 
-	if _, err := store.CreateChatMessage(channel.ID, viewer.ID, "hello"); err != nil {
+	if _, err := store.CreateChatMessage(channel.ID, viewer.ID, "hello", ""); err != nil {
 		t.Fatalf("CreateChatMessage: %v", err)
 	}
 
@@ -250,14 +322,66 @@ func TestChatReportsLifecycle(t *testing.T) {
 	RunRepositoryChatReportsLifecycle(t, jsonRepositoryFactory)
 }
 
+func TestChatMessagesInRange(t *testing.T) {
+	RunRepositoryChatMessagesInRange(t, jsonRepositoryFactory)
+}
+
 func TestRepositoryChannelSearch(t *testing.T) {
 	RunRepositoryChannelSearch(t, jsonRepositoryFactory)
 }
 
+func TestRepositoryUserDeactivation(t *testing.T) {
+	RunRepositoryUserDeactivation(t, jsonRepositoryFactory)
+}
+
+func TestRepositoryUserListing(t *testing.T) {
+	RunRepositoryUserListing(t, jsonRepositoryFactory)
+}
+
 func TestRepositoryChannelLookupByStreamKey(t *testing.T) {
 	RunRepositoryChannelLookupByStreamKey(t, jsonRepositoryFactory)
 }
 
+func TestRepositoryChannelPlaybackRestriction(t *testing.T) {
+	RunRepositoryChannelPlaybackRestriction(t, jsonRepositoryFactory)
+}
+
+func TestRepositoryChannelTranscodeLimits(t *testing.T) {
+	RunRepositoryChannelTranscodeLimits(t, jsonRepositoryFactory)
+}
+
+func TestRepositoryLiveClips(t *testing.T) {
+	RunRepositoryLiveClips(t, jsonRepositoryFactory)
+}
+
+func TestRepositoryStreamMarkers(t *testing.T) {
+	RunRepositoryStreamMarkers(t, jsonRepositoryFactory)
+}
+
+func TestRepositoryMatureContentAndAgeConfirmation(t *testing.T) {
+	RunRepositoryMatureContentAndAgeConfirmation(t, jsonRepositoryFactory)
+}
+
+func TestRepositoryChannelBatchUpdate(t *testing.T) {
+	RunRepositoryChannelBatchUpdate(t, jsonRepositoryFactory)
+}
+
+func TestRepositoryChannelFollowers(t *testing.T) {
+	RunRepositoryChannelFollowers(t, jsonRepositoryFactory)
+}
+
+func TestRepositoryChannelEditorGrants(t *testing.T) {
+	RunRepositoryChannelEditorGrants(t, jsonRepositoryFactory)
+}
+
+func TestRepositoryChannelModeratorGrants(t *testing.T) {
+	RunRepositoryChannelModeratorGrants(t, jsonRepositoryFactory)
+}
+
+func TestRepositoryJobQueue(t *testing.T) {
+	RunRepositoryJobQueue(t, jsonRepositoryFactory)
+}
+
 func TestCloneDatasetCopiesModerationMetadata(t *testing.T) {
 	now := time.Now().UTC()
 	resolvedAt := now
@@ -319,3 +443,30 @@ func TestCloneDatasetCopiesModerationMetadata(t *testing.T) {
 		t.Fatalf("expected subscription cancelledAt pointer to be cloned")
 	}
 }
+
+func TestStorageLoadsLegacyChatReportsAsMessageReports(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	legacy := `{
+  "users": {
+    "owner": {"id": "owner", "displayName": "Owner", "email": "owner@example.com", "createdAt": "2024-01-01T00:00:00Z"},
+    "viewer": {"id": "viewer", "displayName": "Viewer", "email": "viewer@example.com", "createdAt": "2024-01-01T00:00:00Z"}
+  },
+  "channels": {"chan": {"id": "chan", "ownerId": "owner", "title": "Legacy", "tags": [], "liveState": "offline", "createdAt": "2024-01-01T00:00:00Z", "updatedAt": "2024-01-01T00:00:00Z"}},
+  "chatReports": {"report": {"id": "report", "channelId": "chan", "reporterId": "viewer", "targetId": "owner", "reason": "spam", "messageId": "msg", "status": "open", "createdAt": "2024-01-01T00:00:00Z"}}
+}`
+	if err := os.WriteFile(path, []byte(legacy), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	store, err := NewStorage(path)
+	if err != nil {
+		t.Fatalf("NewStorage: %v", err)
+	}
+	reports, err := store.ListReports(ReportFilter{SubjectType: models.ReportSubjectChatMessage, Category: models.ReportCategoryOther})
+	if err != nil {
+		t.Fatalf("ListReports: %v", err)
+	}
+	if len(reports) != 1 || reports[0].ID != "report" || reports[0].SubjectID != "msg" {
+		t.Fatalf("expected the legacy report as a chat message report, got %+v", reports)
+	}
+}
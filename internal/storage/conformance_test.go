@@ -0,0 +1,20 @@
+package storage_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"bitriver-live/internal/ingest"
+	"bitriver-live/internal/storage"
+	"bitriver-live/internal/storage/storagetest"
+)
+
+func TestRepositoryConformance(t *testing.T) {
+	storagetest.RunRepositoryTests(t, func() storage.Repository {
+		store, err := storage.NewStorage(filepath.Join(t.TempDir(), "store.json"), storage.WithIngestController(ingest.NoopController{}), storage.WithIngestRetries(1, 0), storage.WithSecretKey(storagetest.SecretKey))
+		if err != nil {
+			t.Fatalf("open JSON store: %v", err)
+		}
+		return store
+	})
+}
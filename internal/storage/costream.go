@@ -0,0 +1,251 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/models"
+)
+
+// MaxCoStreamGuests caps how many guests a live session can have invited or
+// co-streaming at once.
+const MaxCoStreamGuests = 4
+
+func cloneCoStreamInvite(invite models.CoStreamInvite) models.CoStreamInvite {
+	cloned := invite
+	if invite.RespondedAt != nil {
+		responded := *invite.RespondedAt
+		cloned.RespondedAt = &responded
+	}
+	if invite.EndedAt != nil {
+		ended := *invite.EndedAt
+		cloned.EndedAt = &ended
+	}
+	return cloned
+}
+
+func sortCoStreamInvites(invites []models.CoStreamInvite) {
+	sort.Slice(invites, func(i, j int) bool {
+		if !invites[i].CreatedAt.Equal(invites[j].CreatedAt) {
+			return invites[i].CreatedAt.Before(invites[j].CreatedAt)
+		}
+		return invites[i].ID < invites[j].ID
+	})
+}
+
+// coStreamLiveLocked reports whether the invite's session is still the host
+// channel's live session. Invites of an ended session mirror nothing even
+// if ending it did not mark them.
+func (s *Storage) coStreamLiveLocked(invite models.CoStreamInvite) bool {
+	host, ok := s.data.Channels[invite.HostChannelID]
+	return ok && host.CurrentSessionID != nil && *host.CurrentSessionID == invite.SessionID
+}
+
+// coStreamGuestBusyLocked reports whether the channel is live itself or
+// already co-streaming another live session.
+func (s *Storage) coStreamGuestBusyLocked(channel models.Channel) bool {
+	if channel.CurrentSessionID != nil {
+		return true
+	}
+	for _, invite := range s.data.CoStreamInvites {
+		if invite.GuestChannelID == channel.ID && invite.Status == models.CoStreamInviteAccepted && s.coStreamLiveLocked(invite) {
+			return true
+		}
+	}
+	return false
+}
+
+// CreateCoStreamInvite invites the guest channel to co-stream the host
+// channel's live session.
+func (s *Storage) CreateCoStreamInvite(hostChannelID, guestChannelID, invitedBy string) (models.CoStreamInvite, error) {
+	guestChannelID = strings.TrimSpace(guestChannelID)
+	if guestChannelID == "" {
+		return models.CoStreamInvite{}, invalid("guestChannelId", "guestChannelId is required")
+	}
+	if guestChannelID == hostChannelID {
+		return models.CoStreamInvite{}, invalid("guestChannelId", "a channel cannot co-stream with itself")
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	host, ok := s.data.Channels[hostChannelID]
+	if !ok {
+		return models.CoStreamInvite{}, notFound("channel", hostChannelID)
+	}
+	guest, ok := s.data.Channels[guestChannelID]
+	if !ok {
+		return models.CoStreamInvite{}, notFound("channel", guestChannelID)
+	}
+	if host.CurrentSessionID == nil {
+		return models.CoStreamInvite{}, ErrChannelNotLive
+	}
+	sessionID := *host.CurrentSessionID
+	if s.coStreamGuestBusyLocked(guest) {
+		return models.CoStreamInvite{}, ErrCoStreamGuestBusy
+	}
+	active := 0
+	for _, invite := range s.data.CoStreamInvites {
+		if invite.SessionID != sessionID || !invite.Active() {
+			continue
+		}
+		if invite.GuestChannelID == guestChannelID {
+			return models.CoStreamInvite{}, ErrCoStreamGuestBusy
+		}
+		active++
+	}
+	if active >= MaxCoStreamGuests {
+		return models.CoStreamInvite{}, ErrCoStreamGuestLimit
+	}
+
+	id, err := generateID()
+	if err != nil {
+		return models.CoStreamInvite{}, err
+	}
+	invite := models.CoStreamInvite{
+		ID:             id,
+		SessionID:      sessionID,
+		HostChannelID:  hostChannelID,
+		GuestChannelID: guestChannelID,
+		InvitedBy:      invitedBy,
+		Status:         models.CoStreamInvitePending,
+		CreatedAt:      time.Now().UTC(),
+	}
+	s.data.CoStreamInvites[id] = invite
+	if err := s.persist(); err != nil {
+		delete(s.data.CoStreamInvites, id)
+		return models.CoStreamInvite{}, err
+	}
+	return cloneCoStreamInvite(invite), nil
+}
+
+// GetCoStreamInvite returns the invite with the given ID, whatever its
+// status.
+func (s *Storage) GetCoStreamInvite(id string) (models.CoStreamInvite, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	invite, ok := s.data.CoStreamInvites[id]
+	if !ok {
+		return models.CoStreamInvite{}, false
+	}
+	return cloneCoStreamInvite(invite), true
+}
+
+// RespondCoStreamInvite accepts or declines a pending invite.
+func (s *Storage) RespondCoStreamInvite(id string, accept bool) (models.CoStreamInvite, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	invite, ok := s.data.CoStreamInvites[id]
+	if !ok {
+		return models.CoStreamInvite{}, notFound("co-stream invite", id)
+	}
+	if invite.Status != models.CoStreamInvitePending {
+		return models.CoStreamInvite{}, ErrCoStreamInviteClosed
+	}
+	if !s.coStreamLiveLocked(invite) {
+		return models.CoStreamInvite{}, ErrStreamSessionEnded
+	}
+	if accept {
+		if guest, ok := s.data.Channels[invite.GuestChannelID]; !ok || s.coStreamGuestBusyLocked(guest) {
+			return models.CoStreamInvite{}, ErrCoStreamGuestBusy
+		}
+	}
+
+	now := time.Now().UTC()
+	updated := cloneCoStreamInvite(invite)
+	updated.Status = models.CoStreamInviteDeclined
+	if accept {
+		updated.Status = models.CoStreamInviteAccepted
+	}
+	updated.RespondedAt = &now
+	s.data.CoStreamInvites[id] = updated
+	if err := s.persist(); err != nil {
+		s.data.CoStreamInvites[id] = invite
+		return models.CoStreamInvite{}, err
+	}
+	return cloneCoStreamInvite(updated), nil
+}
+
+// EndCoStreamInvite withdraws a pending invite or removes an accepted
+// guest, which stops the guest channel mirroring the session.
+func (s *Storage) EndCoStreamInvite(id string) (models.CoStreamInvite, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	invite, ok := s.data.CoStreamInvites[id]
+	if !ok {
+		return models.CoStreamInvite{}, notFound("co-stream invite", id)
+	}
+	if !invite.Active() {
+		return models.CoStreamInvite{}, ErrCoStreamInviteClosed
+	}
+	now := time.Now().UTC()
+	updated := cloneCoStreamInvite(invite)
+	updated.Status = models.CoStreamInviteRemoved
+	updated.EndedAt = &now
+	s.data.CoStreamInvites[id] = updated
+	if err := s.persist(); err != nil {
+		s.data.CoStreamInvites[id] = invite
+		return models.CoStreamInvite{}, err
+	}
+	return cloneCoStreamInvite(updated), nil
+}
+
+// ListCoStreamInvites returns the active invites of live sessions that the
+// channel hosts or is invited to, oldest first.
+func (s *Storage) ListCoStreamInvites(channelID string) ([]models.CoStreamInvite, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	invites := make([]models.CoStreamInvite, 0)
+	for _, invite := range s.data.CoStreamInvites {
+		if invite.HostChannelID != channelID && invite.GuestChannelID != channelID {
+			continue
+		}
+		if invite.Active() && s.coStreamLiveLocked(invite) {
+			invites = append(invites, cloneCoStreamInvite(invite))
+		}
+	}
+	sortCoStreamInvites(invites)
+	return invites, nil
+}
+
+// ListActiveCoStreams returns the accepted invites of every live session,
+// oldest first.
+func (s *Storage) ListActiveCoStreams() ([]models.CoStreamInvite, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	invites := make([]models.CoStreamInvite, 0)
+	for _, invite := range s.data.CoStreamInvites {
+		if invite.Status == models.CoStreamInviteAccepted && s.coStreamLiveLocked(invite) {
+			invites = append(invites, cloneCoStreamInvite(invite))
+		}
+	}
+	sortCoStreamInvites(invites)
+	return invites, nil
+}
+
+// endCoStreamInvitesLocked marks the session's active invites ended,
+// returning their previous state for restoreCoStreamInvitesLocked.
+func (s *Storage) endCoStreamInvitesLocked(sessionID string, now time.Time) []models.CoStreamInvite {
+	var previous []models.CoStreamInvite
+	for id, invite := range s.data.CoStreamInvites {
+		if invite.SessionID != sessionID || !invite.Active() {
+			continue
+		}
+		previous = append(previous, invite)
+		ended := cloneCoStreamInvite(invite)
+		ended.Status = models.CoStreamInviteEnded
+		endedAt := now
+		ended.EndedAt = &endedAt
+		s.data.CoStreamInvites[id] = ended
+	}
+	return previous
+}
+
+func (s *Storage) restoreCoStreamInvitesLocked(previous []models.CoStreamInvite) {
+	for _, invite := range previous {
+		s.data.CoStreamInvites[invite.ID] = invite
+	}
+}
@@ -3,46 +3,156 @@ package storage
 import (
 	"fmt"
 	"strings"
-	"unicode"
+	"time"
 	"unicode/utf8"
 
 	"bitriver-live/internal/models"
+	"bitriver-live/internal/wallet"
 )
 
 const (
-	// minDonationAddressLength defines the smallest acceptable donation address length.
-	minDonationAddressLength = 4
 	// MaxDonationAddressLength defines the largest acceptable donation address length.
 	MaxDonationAddressLength = 256
+	// MaxDonationAddresses caps how many donation addresses a profile lists.
+	MaxDonationAddresses = 10
 )
 
-// NormalizeDonationAddress trims and validates a donation address, returning the sanitized value.
-func NormalizeDonationAddress(addr models.CryptoAddress) (models.CryptoAddress, error) {
-	currency := strings.ToUpper(strings.TrimSpace(addr.Currency))
+// donationAddressFormats validates and canonicalizes the address of each
+// supported currency.
+var donationAddressFormats = map[models.CryptoCurrency]func(string) (string, error){
+	models.CryptoCurrencyBTC: wallet.NormalizeBitcoinAddress,
+	models.CryptoCurrencyETH: wallet.NormalizeEthereumAddress,
+	models.CryptoCurrencyXMR: wallet.NormalizeMoneroAddress,
+}
+
+// ParseCryptoCurrency returns the supported currency named by value, ignoring
+// case and surrounding space.
+func ParseCryptoCurrency(value string) (models.CryptoCurrency, error) {
+	currency := models.CryptoCurrency(strings.ToUpper(strings.TrimSpace(value)))
 	if currency == "" {
-		return models.CryptoAddress{}, fmt.Errorf("donation currency is required")
+		return "", fmt.Errorf("donation currency is required")
 	}
-	for _, r := range currency {
-		if r < 'A' || r > 'Z' {
-			return models.CryptoAddress{}, fmt.Errorf("donation currency must contain only uppercase letters")
-		}
+	if _, ok := donationAddressFormats[currency]; !ok {
+		return "", fmt.Errorf("unsupported donation currency %q", value)
+	}
+	return currency, nil
+}
+
+// DonationAddressVerifiable reports whether addresses of currency can be
+// proven with a wallet signature. Only Ethereum signatures can be checked
+// without a full node; other currencies list their addresses unverified.
+func DonationAddressVerifiable(currency models.CryptoCurrency) bool {
+	return currency == models.CryptoCurrencyETH
+}
+
+// NormalizeDonationAddress trims and validates a donation address against
+// the format of its currency, returning the sanitized value. Verification
+// state is dropped; only NormalizeDonationAddresses carries it over.
+func NormalizeDonationAddress(addr models.CryptoAddress) (models.CryptoAddress, error) {
+	currency, err := ParseCryptoCurrency(string(addr.Currency))
+	if err != nil {
+		return models.CryptoAddress{}, err
 	}
 	address := strings.TrimSpace(addr.Address)
 	if address == "" {
 		return models.CryptoAddress{}, fmt.Errorf("donation address is required")
 	}
-	length := utf8.RuneCountInString(address)
-	if length < minDonationAddressLength {
-		return models.CryptoAddress{}, fmt.Errorf("donation address must be at least %d characters", minDonationAddressLength)
-	}
-	if length > MaxDonationAddressLength {
+	if utf8.RuneCountInString(address) > MaxDonationAddressLength {
 		return models.CryptoAddress{}, fmt.Errorf("donation address cannot exceed %d characters", MaxDonationAddressLength)
 	}
-	for _, r := range address {
-		if unicode.IsSpace(r) || !unicode.IsPrint(r) {
-			return models.CryptoAddress{}, fmt.Errorf("donation address contains invalid characters")
-		}
+	address, err = donationAddressFormats[currency](address)
+	if err != nil {
+		return models.CryptoAddress{}, fmt.Errorf("invalid %s donation address: %w", currency, err)
 	}
 	note := strings.TrimSpace(addr.Note)
 	return models.CryptoAddress{Currency: currency, Address: address, Note: note}, nil
 }
+
+// NormalizeDonationAddresses validates the donation addresses replacing
+// previous on a profile. Addresses listed again keep their verification;
+// new or changed ones start unverified.
+func NormalizeDonationAddresses(previous, addresses []models.CryptoAddress) ([]models.CryptoAddress, error) {
+	if len(addresses) > MaxDonationAddresses {
+		return nil, fmt.Errorf("profiles can list at most %d donation addresses", MaxDonationAddresses)
+	}
+	verified := make(map[string]*time.Time, len(previous))
+	for _, addr := range previous {
+		if addr.VerifiedAt != nil {
+			verified[donationAddressKey(addr.Currency, addr.Address)] = addr.VerifiedAt
+		}
+	}
+	normalized := make([]models.CryptoAddress, 0, len(addresses))
+	seen := make(map[string]struct{}, len(addresses))
+	for _, addr := range addresses {
+		clean, err := NormalizeDonationAddress(addr)
+		if err != nil {
+			return nil, err
+		}
+		key := donationAddressKey(clean.Currency, clean.Address)
+		if _, duplicate := seen[key]; duplicate {
+			return nil, fmt.Errorf("duplicate %s donation address", clean.Currency)
+		}
+		seen[key] = struct{}{}
+		if at, ok := verified[key]; ok {
+			verifiedAt := *at
+			clean.VerifiedAt = &verifiedAt
+		}
+		normalized = append(normalized, clean)
+	}
+	return normalized, nil
+}
+
+// markDonationAddressVerified sets the verification time of the listed
+// address of currency. address may be empty when the profile lists a single
+// address for currency.
+func markDonationAddressVerified(addresses []models.CryptoAddress, currency models.CryptoCurrency, address string, at time.Time) error {
+	if !DonationAddressVerifiable(currency) {
+		return ErrDonationAddressUnverifiable
+	}
+	index, err := findDonationAddress(addresses, currency, address)
+	if err != nil {
+		return err
+	}
+	verifiedAt := at.UTC()
+	addresses[index].VerifiedAt = &verifiedAt
+	return nil
+}
+
+// FindDonationAddress returns the address of currency listed on profile.
+// address may be empty when the profile lists a single address for
+// currency; otherwise it picks one of several.
+func FindDonationAddress(profile models.Profile, currency models.CryptoCurrency, address string) (models.CryptoAddress, error) {
+	index, err := findDonationAddress(profile.DonationAddresses, currency, address)
+	if err != nil {
+		return models.CryptoAddress{}, err
+	}
+	return profile.DonationAddresses[index], nil
+}
+
+func findDonationAddress(addresses []models.CryptoAddress, currency models.CryptoCurrency, address string) (int, error) {
+	address = strings.TrimSpace(address)
+	match := -1
+	for i, addr := range addresses {
+		if addr.Currency != currency {
+			continue
+		}
+		if address != "" {
+			if addr.Address == address || (currency == models.CryptoCurrencyETH && strings.EqualFold(addr.Address, address)) {
+				return i, nil
+			}
+			continue
+		}
+		if match >= 0 {
+			return -1, fmt.Errorf("profile lists several %s donation addresses; name the one to verify", currency)
+		}
+		match = i
+	}
+	if match < 0 {
+		return -1, ErrDonationAddressNotFound
+	}
+	return match, nil
+}
+
+func donationAddressKey(currency models.CryptoCurrency, address string) string {
+	return string(currency) + ":" + address
+}
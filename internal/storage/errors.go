@@ -0,0 +1,90 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+)
+
+// Error classes shared by both repositories. Every typed error below matches
+// exactly one of them with errors.Is, through any %w wrapping, so callers can
+// tell a missing record from a clash or a bad request without reading error
+// strings.
+var (
+	// ErrNotFound matches errors for a record that does not exist.
+	ErrNotFound = errors.New("not found")
+	// ErrConflict matches errors for a value another record already holds.
+	ErrConflict = errors.New("conflict")
+	// ErrValidation matches errors for input the repository rejects.
+	ErrValidation = errors.New("validation failed")
+	// ErrPreconditionFailed matches errors for operations the record's
+	// current state does not allow, such as starting a live channel.
+	ErrPreconditionFailed = errors.New("precondition failed")
+)
+
+// NotFoundError reports that no Kind record has ID. ID is empty when the
+// record is looked up by something other than its ID.
+type NotFoundError struct {
+	Kind string
+	ID   string
+}
+
+func (e *NotFoundError) Error() string {
+	if e.ID == "" {
+		return e.Kind + " not found"
+	}
+	return fmt.Sprintf("%s %s not found", e.Kind, e.ID)
+}
+
+// Is makes NotFoundError match ErrNotFound.
+func (e *NotFoundError) Is(target error) bool { return target == ErrNotFound }
+
+// ConflictError reports that Field holds a value another record already
+// uses.
+type ConflictError struct {
+	Field   string
+	Message string
+}
+
+func (e *ConflictError) Error() string { return e.Message }
+
+// Is makes ConflictError match ErrConflict.
+func (e *ConflictError) Is(target error) bool { return target == ErrConflict }
+
+// ValidationError reports why Field was rejected. Field is empty when the
+// problem is not tied to one field.
+type ValidationError struct {
+	Field   string
+	Message string
+}
+
+func (e *ValidationError) Error() string { return e.Message }
+
+// Is makes ValidationError match ErrValidation.
+func (e *ValidationError) Is(target error) bool { return target == ErrValidation }
+
+// PreconditionError reports that the current state of a record does not
+// allow the operation.
+type PreconditionError struct {
+	Reason string
+}
+
+func (e *PreconditionError) Error() string { return e.Reason }
+
+// Is makes PreconditionError match ErrPreconditionFailed.
+func (e *PreconditionError) Is(target error) bool { return target == ErrPreconditionFailed }
+
+func notFound(kind, id string) error {
+	return &NotFoundError{Kind: kind, ID: id}
+}
+
+func conflict(field, format string, args ...any) error {
+	return &ConflictError{Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+func invalid(field, format string, args ...any) error {
+	return &ValidationError{Field: field, Message: fmt.Sprintf(format, args...)}
+}
+
+func precondition(reason string) error {
+	return &PreconditionError{Reason: reason}
+}
@@ -0,0 +1,140 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"bitriver-live/internal/events"
+	"bitriver-live/internal/models"
+)
+
+// eventRepository publishes domain events for changes made through the
+// repository it wraps. Every event is published after the wrapped call has
+// returned without error, which both backends only do once the change is
+// committed. Methods it does not override pass straight through.
+type eventRepository struct {
+	Repository
+	bus *events.Bus
+}
+
+// NewEventRepository wraps repo so the changes listed in package events are
+// published on bus. A nil bus returns repo unchanged.
+func NewEventRepository(repo Repository, bus *events.Bus) Repository {
+	if bus == nil {
+		return repo
+	}
+	return &eventRepository{Repository: repo, bus: bus}
+}
+
+// withPrimaryReads keeps publishing from the primary-only view a request
+// switches to after its own writes.
+func (r *eventRepository) withPrimaryReads() Repository {
+	if reader, ok := r.Repository.(primaryReader); ok {
+		return &eventRepository{Repository: reader.withPrimaryReads(), bus: r.bus}
+	}
+	return r
+}
+
+// Close closes the wrapped repository when it holds resources.
+func (r *eventRepository) Close(ctx context.Context) error {
+	if closer, ok := r.Repository.(interface{ Close(context.Context) error }); ok {
+		return closer.Close(ctx)
+	}
+	return nil
+}
+
+func (r *eventRepository) CreateUser(params CreateUserParams) (models.User, error) {
+	user, err := r.Repository.CreateUser(params)
+	if err == nil {
+		r.bus.Publish(events.UserCreated{User: user})
+	}
+	return user, err
+}
+
+// FollowChannel publishes ChannelFollowed only when the user did not follow
+// the channel already. Two concurrent follows by the same user can both
+// see the channel unfollowed, so subscribers must tolerate a repeat.
+func (r *eventRepository) FollowChannel(userID, channelID string) error {
+	following := events.HasSubscribers[events.ChannelFollowed](r.bus) && r.Repository.IsFollowingChannel(userID, channelID)
+	if err := r.Repository.FollowChannel(userID, channelID); err != nil {
+		return err
+	}
+	if !following {
+		r.bus.Publish(events.ChannelFollowed{UserID: userID, ChannelID: channelID})
+	}
+	return nil
+}
+
+func (r *eventRepository) StartStream(channelID string, renditions []string) (models.StreamSession, error) {
+	session, err := r.Repository.StartStream(channelID, renditions)
+	if err == nil {
+		r.bus.Publish(events.StreamStarted{Session: session})
+	}
+	return session, err
+}
+
+func (r *eventRepository) StartStreamContext(ctx context.Context, channelID string, renditions []string) (models.StreamSession, error) {
+	session, err := r.Repository.StartStreamContext(ctx, channelID, renditions)
+	if err == nil {
+		r.bus.Publish(events.StreamStarted{Session: session})
+	}
+	return session, err
+}
+
+func (r *eventRepository) StopStream(channelID string, peakConcurrent int) (models.StreamSession, error) {
+	session, err := r.Repository.StopStream(channelID, peakConcurrent)
+	if err == nil {
+		r.publishStopped(session)
+	}
+	return session, err
+}
+
+func (r *eventRepository) StopStreamContext(ctx context.Context, channelID string, peakConcurrent int) (models.StreamSession, error) {
+	session, err := r.Repository.StopStreamContext(ctx, channelID, peakConcurrent)
+	if err == nil {
+		r.publishStopped(session)
+	}
+	return session, err
+}
+
+// publishStopped publishes StreamStopped, then RecordingCreated for the
+// recording the stop left behind. The recording is only looked up when
+// someone subscribes to it.
+func (r *eventRepository) publishStopped(session models.StreamSession) {
+	r.bus.Publish(events.StreamStopped{Session: session})
+	if !events.HasSubscribers[events.RecordingCreated](r.bus) {
+		return
+	}
+	recordings, err := r.Repository.ListRecordings(session.ChannelID, true)
+	if err != nil {
+		return
+	}
+	for _, recording := range recordings {
+		if recording.SessionID == session.ID {
+			r.bus.Publish(events.RecordingCreated{Recording: recording})
+			return
+		}
+	}
+}
+
+func (r *eventRepository) PublishRecording(id string) (models.Recording, error) {
+	recording, err := r.Repository.PublishRecording(id)
+	if err == nil {
+		r.bus.Publish(events.RecordingPublished{Recording: recording})
+	}
+	return recording, err
+}
+
+// StartDuePremieres publishes RecordingPublished then RecordingPremiered for
+// each premiere it starts.
+func (r *eventRepository) StartDuePremieres(now time.Time) ([]models.Recording, error) {
+	started, err := r.Repository.StartDuePremieres(now)
+	if err != nil {
+		return started, err
+	}
+	for _, recording := range started {
+		r.bus.Publish(events.RecordingPublished{Recording: recording})
+		r.bus.Publish(events.RecordingPremiered{Recording: recording})
+	}
+	return started, nil
+}
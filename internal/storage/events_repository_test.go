@@ -0,0 +1,210 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/events"
+)
+
+func newEventTestRepository(t *testing.T) (Repository, *events.Bus) {
+	t.Helper()
+	bus := events.NewBus(events.Config{Logger: slog.New(slog.NewTextHandler(io.Discard, nil))})
+	t.Cleanup(func() { _ = bus.Shutdown(context.Background()) })
+	return NewEventRepository(newTestStore(t), bus), bus
+}
+
+func TestEventRepositoryPublishesAfterCommit(t *testing.T) {
+	repo, bus := newEventTestRepository(t)
+	var seen []string
+	fail := func(format string, args ...any) {
+		t.Helper()
+		t.Errorf(format, args...)
+	}
+
+	events.Subscribe(bus, "visibility", func(event events.UserCreated) {
+		if _, ok := repo.GetUser(event.User.ID); !ok {
+			fail("expected user %s to be stored before UserCreated", event.User.ID)
+		}
+		seen = append(seen, event.EventName())
+	})
+	events.Subscribe(bus, "visibility", func(event events.StreamStarted) {
+		if current, ok := repo.CurrentStreamSession(event.Session.ChannelID); !ok || current.ID != event.Session.ID {
+			fail("expected session %s to be live before StreamStarted", event.Session.ID)
+		}
+		seen = append(seen, event.EventName())
+	})
+	events.Subscribe(bus, "visibility", func(event events.StreamStopped) {
+		if _, ok := repo.CurrentStreamSession(event.Session.ChannelID); ok {
+			fail("expected channel %s to be offline before StreamStopped", event.Session.ChannelID)
+		}
+		seen = append(seen, event.EventName())
+	})
+	events.Subscribe(bus, "visibility", func(event events.RecordingCreated) {
+		if _, ok := repo.GetRecording(event.Recording.ID); !ok {
+			fail("expected recording %s to be stored before RecordingCreated", event.Recording.ID)
+		}
+		seen = append(seen, event.EventName())
+	})
+	events.Subscribe(bus, "visibility", func(event events.RecordingPublished) {
+		if stored, ok := repo.GetRecording(event.Recording.ID); !ok || stored.PublishedAt == nil {
+			fail("expected recording %s to be published before RecordingPublished", event.Recording.ID)
+		}
+		seen = append(seen, event.EventName())
+	})
+	events.Subscribe(bus, "visibility", func(event events.ChannelFollowed) {
+		if !repo.IsFollowingChannel(event.UserID, event.ChannelID) {
+			fail("expected the follow to be stored before ChannelFollowed")
+		}
+		seen = append(seen, event.EventName())
+	})
+
+	owner, err := repo.CreateUser(CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := repo.CreateChannel(owner.ID, "Events", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	if _, err := repo.StartStream(channel.ID, []string{"720p"}); err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	if _, err := repo.StopStreamContext(context.Background(), channel.ID, 0); err != nil {
+		t.Fatalf("StopStreamContext: %v", err)
+	}
+	recordings, err := repo.ListRecordings(channel.ID, true)
+	if err != nil || len(recordings) != 1 {
+		t.Fatalf("expected one recording, got %d (err %v)", len(recordings), err)
+	}
+	if _, err := repo.PublishRecording(recordings[0].ID); err != nil {
+		t.Fatalf("PublishRecording: %v", err)
+	}
+	if err := repo.FollowChannel(owner.ID, channel.ID); err != nil {
+		t.Fatalf("FollowChannel: %v", err)
+	}
+	if err := repo.FollowChannel(owner.ID, channel.ID); err != nil {
+		t.Fatalf("repeat FollowChannel: %v", err)
+	}
+
+	want := "user.created,stream.started,stream.stopped,recording.created,recording.published,channel.followed"
+	if got := strings.Join(seen, ","); got != want {
+		t.Fatalf("expected events %s, got %s", want, got)
+	}
+}
+
+func TestEventRepositorySkipsFailedChanges(t *testing.T) {
+	repo, bus := newEventTestRepository(t)
+	var published []string
+	events.Subscribe(bus, "all-streams", func(event events.StreamStarted) { published = append(published, event.EventName()) })
+	events.Subscribe(bus, "all-streams", func(event events.StreamStopped) { published = append(published, event.EventName()) })
+	events.Subscribe(bus, "all-users", func(event events.UserCreated) { published = append(published, event.EventName()) })
+
+	if _, err := repo.StartStream("missing", []string{"720p"}); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("expected starting an unknown channel to fail, got %v", err)
+	}
+	if _, err := repo.StopStream("missing", 0); err == nil {
+		t.Fatal("expected stopping an unknown channel to fail")
+	}
+	if _, err := repo.CreateUser(CreateUserParams{DisplayName: "Nobody"}); err == nil {
+		t.Fatal("expected a user without an email to be rejected")
+	}
+	if len(published) != 0 {
+		t.Fatalf("expected failed changes to publish nothing, got %v", published)
+	}
+}
+
+func TestEventRepositoryAsyncPanicDoesNotFailRequest(t *testing.T) {
+	repo, bus := newEventTestRepository(t)
+	events.Subscribe(bus, "broken", func(events.StreamStarted) { panic("subscriber bug") }, events.Async())
+	events.Subscribe(bus, "broken-sync", func(events.StreamStarted) { panic("subscriber bug") })
+
+	owner, err := repo.CreateUser(CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := repo.CreateChannel(owner.ID, "Resilient", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	session, err := repo.StartStream(channel.ID, []string{"720p"})
+	if err != nil {
+		t.Fatalf("expected StartStream to succeed despite panicking subscribers, got %v", err)
+	}
+	if current, ok := repo.CurrentStreamSession(channel.ID); !ok || current.ID != session.ID {
+		t.Fatal("expected the stream to stay live")
+	}
+	if err := bus.Shutdown(context.Background()); err != nil {
+		t.Fatalf("Shutdown: %v", err)
+	}
+}
+
+func TestEventRepositoryKeepsPrimaryReadsAndClose(t *testing.T) {
+	repo, bus := newEventTestRepository(t)
+	var started int
+	events.Subscribe(bus, "count", func(events.StreamStarted) { started++ })
+
+	primary := RepositoryForContext(ContextWithPrimaryReads(context.Background()), repo)
+	owner, err := primary.CreateUser(CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := primary.CreateChannel(owner.ID, "Primary", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	if _, err := primary.StartStream(channel.ID, []string{"720p"}); err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	if started != 1 {
+		t.Fatalf("expected the primary-reads view to publish, got %d events", started)
+	}
+	closer, ok := repo.(interface{ Close(context.Context) error })
+	if !ok || closer.Close(context.Background()) != nil {
+		t.Fatal("expected the wrapper to expose Close")
+	}
+}
+
+func TestEventRepositoryPublishesStartedPremieres(t *testing.T) {
+	repo, bus := newEventTestRepository(t)
+	var seen []string
+	events.Subscribe(bus, "premieres", func(event events.RecordingPublished) { seen = append(seen, event.EventName()) })
+	events.Subscribe(bus, "premieres", func(event events.RecordingPremiered) { seen = append(seen, event.EventName()) })
+
+	owner, err := repo.CreateUser(CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := repo.CreateChannel(owner.ID, "Premieres", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	if _, err := repo.StartStream(channel.ID, []string{"720p"}); err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	if _, err := repo.StopStream(channel.ID, 0); err != nil {
+		t.Fatalf("StopStream: %v", err)
+	}
+	recordings, err := repo.ListRecordings(channel.ID, true)
+	if err != nil || len(recordings) != 1 {
+		t.Fatalf("expected one recording, got %d (err %v)", len(recordings), err)
+	}
+	premiereAt := time.Now().Add(time.Hour)
+	if _, err := repo.UpdateRecording(recordings[0].ID, RecordingUpdate{PremiereAt: &premiereAt}); err != nil {
+		t.Fatalf("UpdateRecording: %v", err)
+	}
+
+	for i := 0; i < 2; i++ {
+		if _, err := repo.StartDuePremieres(premiereAt.Add(time.Minute)); err != nil {
+			t.Fatalf("StartDuePremieres: %v", err)
+		}
+	}
+	if got := strings.Join(seen, ","); got != "recording.published,recording.premiered" {
+		t.Fatalf("expected one published and premiered pair, got %s", got)
+	}
+}
@@ -0,0 +1,232 @@
+package storage
+
+import (
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"bitriver-live/internal/models"
+)
+
+const (
+	// MaxFeatureFlagKeyLength is the longest feature flag key, in bytes.
+	MaxFeatureFlagKeyLength = 64
+	// MaxFeatureFlagDescriptionLength is the longest flag description, in
+	// characters.
+	MaxFeatureFlagDescriptionLength = 280
+	// MaxFeatureFlagListLength caps each of a flag's allow and deny lists.
+	MaxFeatureFlagListLength = 1000
+)
+
+var featureFlagKeyPattern = regexp.MustCompile(`^[a-z0-9]+([._-][a-z0-9]+)*$`)
+
+func cloneFeatureFlag(flag models.FeatureFlag) models.FeatureFlag {
+	cloned := flag
+	cloned.AllowUserIDs = append([]string(nil), flag.AllowUserIDs...)
+	cloned.DenyUserIDs = append([]string(nil), flag.DenyUserIDs...)
+	return cloned
+}
+
+func normalizeFeatureFlagKey(key string) (string, error) {
+	key = strings.ToLower(strings.TrimSpace(key))
+	if key == "" {
+		return "", invalid("key", "key is required")
+	}
+	if len(key) > MaxFeatureFlagKeyLength {
+		return "", invalid("key", "key must be %d characters or fewer", MaxFeatureFlagKeyLength)
+	}
+	if !featureFlagKeyPattern.MatchString(key) {
+		return "", invalid("key", "key may only contain lowercase letters and digits separated by single dots, hyphens, or underscores")
+	}
+	return key, nil
+}
+
+func normalizeFeatureFlagDescription(description string) (string, error) {
+	description = strings.TrimSpace(description)
+	if utf8.RuneCountInString(description) > MaxFeatureFlagDescriptionLength {
+		return "", invalid("description", "description must be %d characters or fewer", MaxFeatureFlagDescriptionLength)
+	}
+	return description, nil
+}
+
+func validateRolloutPercent(percent int) error {
+	if percent < 0 || percent > 100 {
+		return invalid("rolloutPercent", "rolloutPercent must be between 0 and 100")
+	}
+	return nil
+}
+
+// normalizeFeatureFlagUsers trims the user IDs and drops blanks and
+// duplicates, keeping the first occurrence's position.
+func normalizeFeatureFlagUsers(field string, ids []string) ([]string, error) {
+	seen := make(map[string]struct{}, len(ids))
+	normalized := make([]string, 0, len(ids))
+	for _, id := range ids {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		if _, dup := seen[id]; dup {
+			continue
+		}
+		seen[id] = struct{}{}
+		normalized = append(normalized, id)
+	}
+	if len(normalized) > MaxFeatureFlagListLength {
+		return nil, invalid(field, "%s may list at most %d users", field, MaxFeatureFlagListLength)
+	}
+	return normalized, nil
+}
+
+// newFeatureFlag validates params and builds the flag row.
+func newFeatureFlag(params FeatureFlagParams, now time.Time) (models.FeatureFlag, error) {
+	key, err := normalizeFeatureFlagKey(params.Key)
+	if err != nil {
+		return models.FeatureFlag{}, err
+	}
+	description, err := normalizeFeatureFlagDescription(params.Description)
+	if err != nil {
+		return models.FeatureFlag{}, err
+	}
+	if err := validateRolloutPercent(params.RolloutPercent); err != nil {
+		return models.FeatureFlag{}, err
+	}
+	allow, err := normalizeFeatureFlagUsers("allowUserIds", params.AllowUserIDs)
+	if err != nil {
+		return models.FeatureFlag{}, err
+	}
+	deny, err := normalizeFeatureFlagUsers("denyUserIds", params.DenyUserIDs)
+	if err != nil {
+		return models.FeatureFlag{}, err
+	}
+	return models.FeatureFlag{
+		Key:            key,
+		Description:    description,
+		Enabled:        params.Enabled,
+		RolloutPercent: params.RolloutPercent,
+		AllowUserIDs:   allow,
+		DenyUserIDs:    deny,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}, nil
+}
+
+// applyFeatureFlagUpdate applies update to a copy of flag.
+func applyFeatureFlagUpdate(flag models.FeatureFlag, update FeatureFlagUpdate, now time.Time) (models.FeatureFlag, error) {
+	flag = cloneFeatureFlag(flag)
+	if update.Description != nil {
+		description, err := normalizeFeatureFlagDescription(*update.Description)
+		if err != nil {
+			return models.FeatureFlag{}, err
+		}
+		flag.Description = description
+	}
+	if update.Enabled != nil {
+		flag.Enabled = *update.Enabled
+	}
+	if update.RolloutPercent != nil {
+		if err := validateRolloutPercent(*update.RolloutPercent); err != nil {
+			return models.FeatureFlag{}, err
+		}
+		flag.RolloutPercent = *update.RolloutPercent
+	}
+	if update.AllowUserIDs != nil {
+		allow, err := normalizeFeatureFlagUsers("allowUserIds", *update.AllowUserIDs)
+		if err != nil {
+			return models.FeatureFlag{}, err
+		}
+		flag.AllowUserIDs = allow
+	}
+	if update.DenyUserIDs != nil {
+		deny, err := normalizeFeatureFlagUsers("denyUserIds", *update.DenyUserIDs)
+		if err != nil {
+			return models.FeatureFlag{}, err
+		}
+		flag.DenyUserIDs = deny
+	}
+	flag.UpdatedAt = now
+	return flag, nil
+}
+
+// ListFeatureFlags returns every feature flag ordered by key.
+func (s *Storage) ListFeatureFlags() ([]models.FeatureFlag, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	flags := make([]models.FeatureFlag, 0, len(s.data.FeatureFlags))
+	for _, flag := range s.data.FeatureFlags {
+		flags = append(flags, cloneFeatureFlag(flag))
+	}
+	sort.Slice(flags, func(i, j int) bool { return flags[i].Key < flags[j].Key })
+	return flags, nil
+}
+
+// GetFeatureFlag returns the flag with the given key.
+func (s *Storage) GetFeatureFlag(key string) (models.FeatureFlag, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	flag, ok := s.data.FeatureFlags[key]
+	if !ok {
+		return models.FeatureFlag{}, false
+	}
+	return cloneFeatureFlag(flag), true
+}
+
+// CreateFeatureFlag adds a feature flag. It returns ErrFeatureFlagExists
+// when the key is taken.
+func (s *Storage) CreateFeatureFlag(params FeatureFlagParams) (models.FeatureFlag, error) {
+	flag, err := newFeatureFlag(params, time.Now().UTC())
+	if err != nil {
+		return models.FeatureFlag{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, exists := s.data.FeatureFlags[flag.Key]; exists {
+		return models.FeatureFlag{}, ErrFeatureFlagExists
+	}
+	s.data.FeatureFlags[flag.Key] = flag
+	if err := s.persist(); err != nil {
+		delete(s.data.FeatureFlags, flag.Key)
+		return models.FeatureFlag{}, err
+	}
+	return cloneFeatureFlag(flag), nil
+}
+
+// UpdateFeatureFlag changes a flag's description, switch, rollout, or user
+// lists.
+func (s *Storage) UpdateFeatureFlag(key string, update FeatureFlagUpdate) (models.FeatureFlag, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	current, ok := s.data.FeatureFlags[key]
+	if !ok {
+		return models.FeatureFlag{}, ErrFeatureFlagNotFound
+	}
+	updated, err := applyFeatureFlagUpdate(current, update, time.Now().UTC())
+	if err != nil {
+		return models.FeatureFlag{}, err
+	}
+	s.data.FeatureFlags[key] = updated
+	if err := s.persist(); err != nil {
+		s.data.FeatureFlags[key] = current
+		return models.FeatureFlag{}, err
+	}
+	return cloneFeatureFlag(updated), nil
+}
+
+// DeleteFeatureFlag removes a flag, which turns its feature off for
+// everyone.
+func (s *Storage) DeleteFeatureFlag(key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	flag, ok := s.data.FeatureFlags[key]
+	if !ok {
+		return ErrFeatureFlagNotFound
+	}
+	delete(s.data.FeatureFlags, key)
+	if err := s.persist(); err != nil {
+		s.data.FeatureFlags[key] = flag
+		return err
+	}
+	return nil
+}
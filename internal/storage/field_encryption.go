@@ -0,0 +1,256 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"bitriver-live/internal/models"
+)
+
+// Sensitive fields encrypted at rest with the secret keyring: donation
+// addresses on profiles and the subject and email of linked OAuth accounts.
+// The repositories seal them on write and open them on read, so callers always
+// see plaintext. A sealed OAuth subject is found through its blind index, a
+// keyed digest of provider and subject stored beside it. Snapshots carry the
+// values as stored, so they import under the same keys.
+
+// EncryptionRotation counts the values RotateEncryptedFields sealed again
+// under the primary encryption key.
+type EncryptionRotation struct {
+	DonationAddresses int
+	OAuthSubjects     int
+	OAuthEmails       int
+	RestreamKeys      int
+}
+
+// Total is the number of values rotated.
+func (r EncryptionRotation) Total() int {
+	return r.DonationAddresses + r.OAuthSubjects + r.OAuthEmails + r.RestreamKeys
+}
+
+// EncryptionRotator re-encrypts stored secrets under the primary encryption
+// key. Both repositories implement it.
+type EncryptionRotator interface {
+	RotateEncryptedFields(ctx context.Context, batchSize int) (EncryptionRotation, error)
+}
+
+var (
+	_ EncryptionRotator = (*Storage)(nil)
+	_ EncryptionRotator = (*postgresRepository)(nil)
+)
+
+// DefaultEncryptionRotationBatchSize is how many rows RotateEncryptedFields
+// rewrites per transaction when no batch size is given.
+const DefaultEncryptionRotationBatchSize = 500
+
+func donationAddressOwner(userID string) string {
+	return "profiles/" + userID + "/donation_addresses"
+}
+
+func oauthEmailOwner(provider, subject string) string {
+	return "oauth_accounts/" + provider + "/" + subject + "/email"
+}
+
+// oauthSubjectOwner binds a sealed subject to the blind index stored with it.
+func oauthSubjectOwner(provider, index string) string {
+	return "oauth_accounts/" + provider + "/" + index + "/subject"
+}
+
+// oauthSubjectIndex is the blind index of subject at provider under the
+// primary key, or empty when no key is configured.
+func oauthSubjectIndex(secrets *secretSealer, provider, subject string) string {
+	return secrets.blindIndex(oauthAccountKey(provider, subject))
+}
+
+// oauthSubjectIndexes lists the blind indexes subject may be stored under,
+// one per configured key.
+func oauthSubjectIndexes(secrets *secretSealer, provider, subject string) []string {
+	return secrets.blindIndexes(oauthAccountKey(provider, subject))
+}
+
+// sealOAuthSubject returns the value stored for subject and its blind index.
+// Without a key the subject is stored as is and the index is empty.
+func sealOAuthSubject(secrets *secretSealer, provider, subject string) (string, string, error) {
+	index := oauthSubjectIndex(secrets, provider, subject)
+	if index == "" {
+		return subject, "", nil
+	}
+	sealed, err := secrets.EncryptString(oauthSubjectOwner(provider, index), subject)
+	if err != nil {
+		return "", "", fmt.Errorf("encrypt oauth subject: %w", err)
+	}
+	return sealed, index, nil
+}
+
+// openOAuthSubject returns the plaintext of a stored subject.
+func openOAuthSubject(secrets *secretSealer, provider, subject, index string) (string, error) {
+	opened, err := secrets.DecryptString(oauthSubjectOwner(provider, index), subject)
+	if err != nil {
+		return "", fmt.Errorf("decrypt oauth subject for %s account: %w", provider, err)
+	}
+	return opened, nil
+}
+
+// sealDonationAddresses returns a copy of addresses with each address
+// encrypted for userID.
+func sealDonationAddresses(secrets *secretSealer, userID string, addresses []models.CryptoAddress) ([]models.CryptoAddress, error) {
+	if secrets == nil || len(addresses) == 0 {
+		return addresses, nil
+	}
+	sealed := make([]models.CryptoAddress, len(addresses))
+	for i, address := range addresses {
+		value, err := secrets.EncryptString(donationAddressOwner(userID), address.Address)
+		if err != nil {
+			return nil, fmt.Errorf("encrypt donation address: %w", err)
+		}
+		address.Address = value
+		sealed[i] = address
+	}
+	return sealed, nil
+}
+
+// openDonationAddresses decrypts the addresses of userID in place.
+func openDonationAddresses(secrets *secretSealer, userID string, addresses []models.CryptoAddress) error {
+	for i := range addresses {
+		value, err := secrets.DecryptString(donationAddressOwner(userID), addresses[i].Address)
+		if err != nil {
+			return fmt.Errorf("decrypt donation address of user %s: %w", userID, err)
+		}
+		addresses[i].Address = value
+	}
+	return nil
+}
+
+// sealDatasetFields returns data with its sensitive fields encrypted for
+// writing to disk. The maps holding them are copied so the in-memory dataset
+// keeps plaintext.
+func sealDatasetFields(secrets *secretSealer, data dataset) (dataset, error) {
+	if secrets == nil {
+		return data, nil
+	}
+	if data.Profiles != nil {
+		profiles := make(map[string]models.Profile, len(data.Profiles))
+		for id, profile := range data.Profiles {
+			addresses, err := sealDonationAddresses(secrets, profile.UserID, profile.DonationAddresses)
+			if err != nil {
+				return dataset{}, err
+			}
+			profile.DonationAddresses = addresses
+			profiles[id] = profile
+		}
+		data.Profiles = profiles
+	}
+	if data.OAuthAccounts != nil {
+		accounts := make(map[string]models.OAuthAccount, len(data.OAuthAccounts))
+		for key, account := range data.OAuthAccounts {
+			email, err := secrets.EncryptString(oauthEmailOwner(account.Provider, account.Subject), account.Email)
+			if err != nil {
+				return dataset{}, fmt.Errorf("encrypt oauth email: %w", err)
+			}
+			account.Email = email
+			subject, index, err := sealOAuthSubject(secrets, account.Provider, account.Subject)
+			if err != nil {
+				return dataset{}, err
+			}
+			if index != "" {
+				// Key the sealed account by its index so the file never
+				// names the subject.
+				key = oauthAccountKey(account.Provider, index)
+			}
+			account.Subject, account.SubjectIndex = subject, index
+			accounts[key] = account
+		}
+		data.OAuthAccounts = accounts
+	}
+	return data, nil
+}
+
+// openDatasetFields decrypts the sensitive fields of a dataset read from disk
+// in place.
+func openDatasetFields(secrets *secretSealer, data *dataset) error {
+	for _, profile := range data.Profiles {
+		if err := openDonationAddresses(secrets, profile.UserID, profile.DonationAddresses); err != nil {
+			return err
+		}
+	}
+	if data.OAuthAccounts == nil {
+		return nil
+	}
+	accounts := make(map[string]models.OAuthAccount, len(data.OAuthAccounts))
+	for key, account := range data.OAuthAccounts {
+		subject, err := openOAuthSubject(secrets, account.Provider, account.Subject, account.SubjectIndex)
+		if err != nil {
+			return err
+		}
+		if account.SubjectIndex != "" {
+			key = oauthAccountKey(account.Provider, subject)
+		}
+		account.Subject, account.SubjectIndex = subject, ""
+		email, err := secrets.DecryptString(oauthEmailOwner(account.Provider, account.Subject), account.Email)
+		if err != nil {
+			return fmt.Errorf("decrypt oauth email for %s account: %w", account.Provider, err)
+		}
+		account.Email = email
+		accounts[key] = account
+	}
+	data.OAuthAccounts = accounts
+	return nil
+}
+
+// RotateEncryptedFields rewrites the datastore file so every sensitive value,
+// including restream stream keys, is sealed under the primary encryption key.
+// The JSON datastore is rewritten in one pass, so batchSize is ignored.
+func (s *Storage) RotateEncryptedFields(ctx context.Context, batchSize int) (EncryptionRotation, error) {
+	if err := ctx.Err(); err != nil {
+		return EncryptionRotation{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.secrets == nil {
+		return EncryptionRotation{}, ErrSecretKeyUnavailable
+	}
+
+	// Memory holds these fields opened, so count what the file still
+	// stores in plaintext or under a retired key.
+	var rotation EncryptionRotation
+	onDisk, err := s.readDatasetFile()
+	if err != nil {
+		return EncryptionRotation{}, err
+	}
+	for _, profile := range onDisk.Profiles {
+		for _, address := range profile.DonationAddresses {
+			if s.secrets.needsRotation(address.Address) {
+				rotation.DonationAddresses++
+			}
+		}
+	}
+	for _, account := range onDisk.OAuthAccounts {
+		if s.secrets.needsRotation(account.Subject) {
+			rotation.OAuthSubjects++
+		}
+		if s.secrets.needsRotation(account.Email) {
+			rotation.OAuthEmails++
+		}
+	}
+
+	data := s.data
+	if s.data.RestreamTargets != nil {
+		data.RestreamTargets = make(map[string]models.RestreamTarget, len(s.data.RestreamTargets))
+		for id, target := range s.data.RestreamTargets {
+			if s.secrets.needsRotation(target.StreamKeyCiphertext) {
+				sealed, err := s.secrets.reseal(target.ID, target.StreamKeyCiphertext)
+				if err != nil {
+					return EncryptionRotation{}, fmt.Errorf("rotate stream key of restream target %s: %w", target.ID, err)
+				}
+				target.StreamKeyCiphertext = sealed
+				rotation.RestreamKeys++
+			}
+			data.RestreamTargets[id] = target
+		}
+	}
+	if err := s.persistDataset(data); err != nil {
+		return EncryptionRotation{}, err
+	}
+	s.data = data
+	return rotation, nil
+}
@@ -0,0 +1,232 @@
+package storage
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"bitriver-live/internal/models"
+)
+
+var (
+	testEncryptionKeyOld = EncryptionKey{ID: "k1", Key: []byte("0123456789abcdef0123456789abcdef")}
+	testEncryptionKeyNew = EncryptionKey{ID: "k2", Key: []byte("fedcba9876543210fedcba9876543210")}
+)
+
+func TestParseEncryptionKeys(t *testing.T) {
+	keys, err := ParseEncryptionKeys(" k2:" + hex.EncodeToString(testEncryptionKeyNew.Key) + ", k1:" + hex.EncodeToString(testEncryptionKeyOld.Key) + " ")
+	if err != nil {
+		t.Fatalf("ParseEncryptionKeys: %v", err)
+	}
+	if len(keys) != 2 || keys[0].ID != "k2" || string(keys[0].Key) != string(testEncryptionKeyNew.Key) || keys[1].ID != "k1" {
+		t.Fatalf("unexpected keyring %+v", keys)
+	}
+	for _, raw := range []string{
+		"",
+		hex.EncodeToString(testEncryptionKeyOld.Key),
+		"k1:short",
+		"bad id:" + hex.EncodeToString(testEncryptionKeyOld.Key),
+		"k1:" + hex.EncodeToString(testEncryptionKeyOld.Key) + ",k1:" + hex.EncodeToString(testEncryptionKeyNew.Key),
+	} {
+		if _, err := ParseEncryptionKeys(raw); err == nil {
+			t.Errorf("expected %q to be rejected", raw)
+		}
+	}
+}
+
+func TestEncryptStringRoundTripAndTamperDetection(t *testing.T) {
+	sealer, err := newSecretKeyring(nil, []EncryptionKey{testEncryptionKeyOld})
+	if err != nil {
+		t.Fatalf("newSecretKeyring: %v", err)
+	}
+	sealed, err := sealer.EncryptString("owner-1", "0xabc")
+	if err != nil {
+		t.Fatalf("EncryptString: %v", err)
+	}
+	if !strings.HasPrefix(sealed, "enc:v2:k1:") || strings.Contains(sealed, "0xabc") {
+		t.Fatalf("expected a keyed ciphertext, got %q", sealed)
+	}
+	if opened, err := sealer.DecryptString("owner-1", sealed); err != nil || opened != "0xabc" {
+		t.Fatalf("DecryptString = %q, %v", opened, err)
+	}
+	if empty, err := sealer.EncryptString("owner-1", ""); err != nil || empty != "" {
+		t.Fatalf("expected empty values to stay empty, got %q, %v", empty, err)
+	}
+
+	// Flip one character of the ciphertext body.
+	tampered := []byte(sealed)
+	idx := len(tampered) - 4
+	if tampered[idx] == 'A' {
+		tampered[idx] = 'B'
+	} else {
+		tampered[idx] = 'A'
+	}
+	if _, err := sealer.DecryptString("owner-1", string(tampered)); !errors.Is(err, errSealedSecretInvalid) {
+		t.Fatalf("expected a modified ciphertext to fail, got %v", err)
+	}
+	if _, err := sealer.DecryptString("owner-2", sealed); !errors.Is(err, errSealedSecretInvalid) {
+		t.Fatalf("expected a value moved to another owner to fail, got %v", err)
+	}
+	if _, err := sealer.DecryptString("owner-1", "enc:v9:whatever"); !errors.Is(err, errSealedSecretInvalid) {
+		t.Fatalf("expected an unknown format to fail, got %v", err)
+	}
+
+	// Values from before encryption read back unchanged.
+	if opened, err := sealer.DecryptString("owner-1", "0xlegacy"); err != nil || opened != "0xlegacy" {
+		t.Fatalf("expected legacy plaintext to pass through, got %q, %v", opened, err)
+	}
+	var disabled *secretSealer
+	if opened, err := disabled.DecryptString("owner-1", "0xlegacy"); err != nil || opened != "0xlegacy" {
+		t.Fatalf("expected plaintext without a keyring, got %q, %v", opened, err)
+	}
+	if _, err := disabled.DecryptString("owner-1", sealed); !errors.Is(err, ErrSecretKeyUnavailable) {
+		t.Fatalf("expected a sealed value without a keyring to fail, got %v", err)
+	}
+
+	rotated, err := newSecretKeyring(nil, []EncryptionKey{testEncryptionKeyNew})
+	if err != nil {
+		t.Fatalf("newSecretKeyring: %v", err)
+	}
+	if _, err := rotated.DecryptString("owner-1", sealed); !errors.Is(err, ErrEncryptionKeyMissing) || !strings.Contains(err.Error(), `"k1"`) {
+		t.Fatalf("expected a missing key to be named, got %v", err)
+	}
+}
+
+func TestSecretKeyringOpensSecretKeyValues(t *testing.T) {
+	secretKey := []byte("abcdefghijklmnopqrstuvwxyz012345")
+	legacy, err := newSecretSealer(secretKey)
+	if err != nil {
+		t.Fatalf("newSecretSealer: %v", err)
+	}
+	sealed, err := legacy.seal("target-1", "live_secret")
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	keyring, err := newSecretKeyring(secretKey, []EncryptionKey{testEncryptionKeyNew})
+	if err != nil {
+		t.Fatalf("newSecretKeyring: %v", err)
+	}
+	if opened, err := keyring.open("target-1", sealed); err != nil || opened != "live_secret" {
+		t.Fatalf("open = %q, %v", opened, err)
+	}
+	if !keyring.needsRotation(sealed) {
+		t.Fatal("expected a secret key value to need rotation once named keys are configured")
+	}
+	resealed, err := keyring.reseal("target-1", sealed)
+	if err != nil || !strings.HasPrefix(resealed, "enc:v2:k2:") || keyring.needsRotation(resealed) {
+		t.Fatalf("reseal = %q, %v", resealed, err)
+	}
+}
+
+func TestJSONStoreEncryptsSensitiveFieldsAndRotatesKeys(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+
+	// A deployment from before encryption stores plaintext.
+	plain, err := NewStorage(path)
+	if err != nil {
+		t.Fatalf("NewStorage: %v", err)
+	}
+	owner, err := plain.CreateUser(CreateUserParams{DisplayName: "Owner", Email: "owner@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	legacyAddresses := []models.CryptoAddress{{Currency: "btc", Address: "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"}}
+	if _, err := plain.UpsertProfile(owner.ID, ProfileUpdate{DonationAddresses: &legacyAddresses}); err != nil {
+		t.Fatalf("UpsertProfile: %v", err)
+	}
+	assertFileContains(t, path, "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", true)
+
+	// Enabling k1 keeps the plaintext readable and seals new writes.
+	store, err := NewStorage(path, WithEncryptionKeys([]EncryptionKey{testEncryptionKeyOld}))
+	if err != nil {
+		t.Fatalf("NewStorage with k1: %v", err)
+	}
+	if profile, ok := store.GetProfile(owner.ID); !ok || len(profile.DonationAddresses) != 1 || profile.DonationAddresses[0].Address != "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4" {
+		t.Fatalf("expected the legacy address to stay readable, got %+v", profile.DonationAddresses)
+	}
+	viewer, err := store.AuthenticateOAuth(OAuthLoginParams{Provider: "github", Subject: "oauth-subject-1234", Email: "viewer@example.com", DisplayName: "Viewer"})
+	if err != nil {
+		t.Fatalf("AuthenticateOAuth: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Main", "music", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	if _, err := store.CreateRestreamTarget(channel.ID, RestreamTargetParams{Label: "Mirror", URL: "rtmp://a.example.com/app", StreamKey: "key-a", Enabled: true}); err != nil {
+		t.Fatalf("CreateRestreamTarget: %v", err)
+	}
+	assertFileContains(t, path, "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4", false)
+	assertFileContains(t, path, "enc:v2:k1:", true)
+	onDisk, err := store.readDatasetFile()
+	if err != nil {
+		t.Fatalf("readDatasetFile: %v", err)
+	}
+	for key, account := range onDisk.OAuthAccounts {
+		if !strings.HasPrefix(account.Email, "enc:v2:k1:") {
+			t.Fatalf("expected the oauth email to be sealed on disk, got %q", account.Email)
+		}
+		if !strings.HasPrefix(account.Subject, "enc:v2:k1:") || account.SubjectIndex == "" || key != oauthAccountKey("github", account.SubjectIndex) {
+			t.Fatalf("expected the oauth subject to be sealed and keyed by its index, got %q: %+v", key, account)
+		}
+	}
+	assertFileContains(t, path, "oauth-subject-1234", false)
+
+	// Rotate to k2 while k1 still opens the old values.
+	rotating, err := NewStorage(path, WithEncryptionKeys([]EncryptionKey{testEncryptionKeyNew, testEncryptionKeyOld}))
+	if err != nil {
+		t.Fatalf("NewStorage with k2,k1: %v", err)
+	}
+	rotation, err := rotating.RotateEncryptedFields(context.Background(), 0)
+	if err != nil {
+		t.Fatalf("RotateEncryptedFields: %v", err)
+	}
+	if rotation.DonationAddresses != 1 || rotation.OAuthSubjects != 1 || rotation.OAuthEmails != 1 || rotation.RestreamKeys != 1 {
+		t.Fatalf("unexpected rotation counts %+v", rotation)
+	}
+	assertFileContains(t, path, "enc:v2:k1:", false)
+	if again, err := rotating.RotateEncryptedFields(context.Background(), 0); err != nil || again.Total() != 0 {
+		t.Fatalf("expected a second rotation to find nothing, got %+v, %v", again, err)
+	}
+
+	// k1 can now be retired.
+	rotated, err := NewStorage(path, WithEncryptionKeys([]EncryptionKey{testEncryptionKeyNew}))
+	if err != nil {
+		t.Fatalf("NewStorage with k2: %v", err)
+	}
+	if profile, ok := rotated.GetProfile(owner.ID); !ok || profile.DonationAddresses[0].Address != "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4" {
+		t.Fatalf("expected the address to survive rotation, got %+v", profile.DonationAddresses)
+	}
+	targets, err := rotated.ListRestreamTargets(channel.ID)
+	if err != nil || len(targets) != 1 {
+		t.Fatalf("ListRestreamTargets: %v (%d)", err, len(targets))
+	}
+	if restreams := bootRestreams(rotated.secrets, targets); len(restreams) != 1 || restreams[0].StreamKey != "key-a" {
+		t.Fatalf("expected the rotated stream key to open, got %+v", restreams)
+	}
+	if user, err := rotated.AuthenticateOAuth(OAuthLoginParams{Provider: "github", Subject: "oauth-subject-1234"}); err != nil || user.ID != viewer.ID {
+		t.Fatalf("expected the rotated oauth account to sign in as %s, got %+v, %v", viewer.ID, user, err)
+	}
+
+	// Losing the key fails loudly instead of serving ciphertext.
+	if _, err := NewStorage(path, WithEncryptionKeys([]EncryptionKey{testEncryptionKeyOld})); !errors.Is(err, ErrEncryptionKeyMissing) {
+		t.Fatalf("expected a missing key error, got %v", err)
+	}
+	if _, err := NewStorage(path); !errors.Is(err, ErrSecretKeyUnavailable) {
+		t.Fatalf("expected an unavailable key error without a keyring, got %v", err)
+	}
+}
+
+func assertFileContains(t *testing.T, path, needle string, want bool) {
+	t.Helper()
+	data, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read store file: %v", err)
+	}
+	if got := strings.Contains(string(data), needle); got != want {
+		t.Fatalf("expected store file contains %q to be %v", needle, want)
+	}
+}
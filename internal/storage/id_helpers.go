@@ -2,11 +2,19 @@ package storage
 
 import (
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
 	"fmt"
 	"strings"
+
+	"bitriver-live/internal/models"
 )
 
+// streamKeyHintLength is how many leading and trailing characters of a
+// stream key are kept so creators can tell keys apart in the UI.
+const streamKeyHintLength = 4
+
 func generateID() (string, error) {
 	bytes := make([]byte, 16)
 	if _, err := rand.Read(bytes); err != nil {
@@ -22,3 +30,59 @@ func generateStreamKey() (string, error) {
 	}
 	return strings.ToUpper(hex.EncodeToString(bytes)), nil
 }
+
+// newChannelStreamKey generates a stream key and returns the plaintext
+// alongside the digest and display hint that repositories persist.
+func newChannelStreamKey() (string, string, string, error) {
+	streamKey, err := generateStreamKey()
+	if err != nil {
+		return "", "", "", err
+	}
+	return streamKey, HashStreamKey(streamKey), streamKeyHint(streamKey), nil
+}
+
+// HashStreamKey returns the SHA-256 digest stored in place of a channel's
+// stream key. Stream keys are 192-bit random tokens, so a fast digest is
+// sufficient, matching how session and API tokens are stored.
+func HashStreamKey(streamKey string) string {
+	digest := sha256.Sum256([]byte(strings.TrimSpace(streamKey)))
+	return hex.EncodeToString(digest[:])
+}
+
+// StreamKeyMatches reports whether streamKey hashes to the digest stored on
+// the channel, comparing in constant time.
+func StreamKeyMatches(channel models.Channel, streamKey string) bool {
+	if strings.TrimSpace(streamKey) == "" || channel.StreamKeyHash == "" {
+		return false
+	}
+	candidate := HashStreamKey(streamKey)
+	return subtle.ConstantTimeCompare([]byte(candidate), []byte(channel.StreamKeyHash)) == 1
+}
+
+func streamKeyHint(streamKey string) string {
+	if len(streamKey) <= streamKeyHintLength*2 {
+		return strings.Repeat("*", len(streamKey))
+	}
+	return streamKey[:streamKeyHintLength] + "…" + streamKey[len(streamKey)-streamKeyHintLength:]
+}
+
+// hashPlaintextStreamKeys replaces any plaintext stream keys left over from
+// datastores written before keys were hashed. It reports whether a channel
+// was rewritten.
+func hashPlaintextStreamKeys(channels map[string]models.Channel) bool {
+	changed := false
+	for id, channel := range channels {
+		plaintext := strings.TrimSpace(channel.StreamKey)
+		if plaintext == "" {
+			continue
+		}
+		if channel.StreamKeyHash == "" {
+			channel.StreamKeyHash = HashStreamKey(plaintext)
+			channel.StreamKeyHint = streamKeyHint(plaintext)
+		}
+		channel.StreamKey = ""
+		channels[id] = channel
+		changed = true
+	}
+	return changed
+}
@@ -4,9 +4,20 @@ import "time"
 
 const defaultIngestOperationTimeout = 12 * time.Second
 
+// defaultStartingTimeout comfortably covers a boot that exhausts its retries
+// at the default ingest timeout.
+const defaultStartingTimeout = 2 * time.Minute
+
 func normalizeIngestTimeout(timeout time.Duration) time.Duration {
 	if timeout <= 0 {
 		return defaultIngestOperationTimeout
 	}
 	return timeout
 }
+
+func normalizeStartingTimeout(timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		return defaultStartingTimeout
+	}
+	return timeout
+}
@@ -0,0 +1,88 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"bitriver-live/internal/models"
+)
+
+// applyStreamMedia records on session whether the ingest server reports
+// media arriving at at. The first report of media stamps MediaConfirmedAt;
+// losing it stamps InterruptedAt once, so repeated idle reports do not push
+// the interruption later.
+func applyStreamMedia(session *models.StreamSession, receiving bool, at time.Time) {
+	at = at.UTC()
+	session.ReceivingMedia = receiving
+	if receiving {
+		if session.MediaConfirmedAt == nil {
+			session.MediaConfirmedAt = &at
+		}
+		session.InterruptedAt = nil
+		return
+	}
+	if session.InterruptedAt == nil {
+		session.InterruptedAt = &at
+	}
+}
+
+// sortInterruptedSessions orders sessions by when they were interrupted,
+// longest first.
+func sortInterruptedSessions(sessions []models.StreamSession) {
+	sort.Slice(sessions, func(i, j int) bool {
+		if !sessions[i].InterruptedAt.Equal(*sessions[j].InterruptedAt) {
+			return sessions[i].InterruptedAt.Before(*sessions[j].InterruptedAt)
+		}
+		return sessions[i].ID < sessions[j].ID
+	})
+}
+
+// RecordStreamMedia marks whether the channel's live session is receiving
+// media, as reported by the ingest server at at. It returns
+// ErrChannelNotLive when the channel has no session.
+func (s *Storage) RecordStreamMedia(channelID string, receiving bool, at time.Time) (models.StreamSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	channel, ok := s.data.Channels[channelID]
+	if !ok {
+		return models.StreamSession{}, notFound("channel", channelID)
+	}
+	if channel.CurrentSessionID == nil {
+		return models.StreamSession{}, ErrChannelNotLive
+	}
+	session, ok := s.data.StreamSessions[*channel.CurrentSessionID]
+	if !ok {
+		return models.StreamSession{}, fmt.Errorf("session %s missing", *channel.CurrentSessionID)
+	}
+	original := session
+	applyStreamMedia(&session, receiving, at)
+	s.data.StreamSessions[session.ID] = session
+	if err := s.persist(); err != nil {
+		s.data.StreamSessions[session.ID] = original
+		return models.StreamSession{}, err
+	}
+	return session, nil
+}
+
+// ListInterruptedStreamSessions returns the live sessions whose media
+// stopped at or before interruptedBefore, longest interrupted first.
+func (s *Storage) ListInterruptedStreamSessions(interruptedBefore time.Time) ([]models.StreamSession, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	sessions := make([]models.StreamSession, 0)
+	for _, channel := range s.data.Channels {
+		if channel.CurrentSessionID == nil {
+			continue
+		}
+		session, ok := s.data.StreamSessions[*channel.CurrentSessionID]
+		if !ok || session.InterruptedAt == nil || session.InterruptedAt.After(interruptedBefore) {
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	sortInterruptedSessions(sessions)
+	return sessions, nil
+}
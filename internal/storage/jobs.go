@@ -0,0 +1,270 @@
+package storage
+
+import (
+	"encoding/json"
+	"errors"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Job statuses. Completed jobs are deleted rather than kept, so a job is
+// either waiting to run, claimed by a worker, or failed for good.
+const (
+	JobStatusPending = "pending"
+	JobStatusRunning = "running"
+	JobStatusFailed  = "failed"
+)
+
+// ErrJobNotClaimed is returned when a worker completes or fails a job it no
+// longer holds, typically because its lease expired and another worker
+// reclaimed the job.
+var ErrJobNotClaimed = errors.New("job is not claimed by this worker")
+
+// Job is a unit of background work persisted so it survives restarts.
+// Jobs are not carried across snapshot imports; queues are expected to be
+// drained before migrating between backends.
+type Job struct {
+	ID      string          `json:"id"`
+	Type    string          `json:"type"`
+	Key     string          `json:"key,omitempty"`
+	Payload json.RawMessage `json:"payload"`
+	Status  string          `json:"status"`
+	RunAt   time.Time       `json:"runAt"`
+	// Attempts counts claims, including the one in progress.
+	Attempts    int        `json:"attempts"`
+	LastError   string     `json:"lastError,omitempty"`
+	LockedBy    string     `json:"lockedBy,omitempty"`
+	LockedUntil *time.Time `json:"lockedUntil,omitempty"`
+	CreatedAt   time.Time  `json:"createdAt"`
+	UpdatedAt   time.Time  `json:"updatedAt"`
+}
+
+// EnqueueJobParams describes a job to add to the queue. Key deduplicates
+// jobs: while a pending or running job holds a key, enqueueing another job
+// with the same key returns the existing job instead. A zero RunAt runs the
+// job as soon as a worker is free.
+type EnqueueJobParams struct {
+	Type    string
+	Key     string
+	Payload json.RawMessage
+	RunAt   time.Time
+}
+
+// normalized validates the params and fills in defaults.
+func (p EnqueueJobParams) normalized(now time.Time) (EnqueueJobParams, error) {
+	p.Type = strings.TrimSpace(p.Type)
+	p.Key = strings.TrimSpace(p.Key)
+	if p.Type == "" {
+		return EnqueueJobParams{}, errors.New("job type is required")
+	}
+	if len(p.Payload) == 0 {
+		p.Payload = json.RawMessage("{}")
+	} else if !json.Valid(p.Payload) {
+		return EnqueueJobParams{}, errors.New("job payload must be valid JSON")
+	}
+	if p.RunAt.IsZero() {
+		p.RunAt = now
+	}
+	p.RunAt = p.RunAt.UTC()
+	return p, nil
+}
+
+func cloneJob(job Job) Job {
+	cloned := job
+	if job.Payload != nil {
+		cloned.Payload = append(json.RawMessage(nil), job.Payload...)
+	}
+	if job.LockedUntil != nil {
+		lockedUntil := *job.LockedUntil
+		cloned.LockedUntil = &lockedUntil
+	}
+	return cloned
+}
+
+// jobClaimable reports whether a job is due, or was claimed by a worker
+// whose lease has since expired.
+func jobClaimable(job Job, now time.Time) bool {
+	switch job.Status {
+	case JobStatusPending:
+		return !job.RunAt.After(now)
+	case JobStatusRunning:
+		return job.LockedUntil == nil || !job.LockedUntil.After(now)
+	default:
+		return false
+	}
+}
+
+// EnqueueJob adds a job to the queue.
+func (s *Storage) EnqueueJob(params EnqueueJobParams) (Job, error) {
+	now := time.Now().UTC()
+	params, err := params.normalized(now)
+	if err != nil {
+		return Job{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if params.Key != "" {
+		for _, job := range s.data.Jobs {
+			if job.Key == params.Key && job.Status != JobStatusFailed {
+				return cloneJob(job), nil
+			}
+		}
+	}
+
+	id, err := generateID()
+	if err != nil {
+		return Job{}, err
+	}
+	job := Job{
+		ID:        id,
+		Type:      params.Type,
+		Key:       params.Key,
+		Payload:   params.Payload,
+		Status:    JobStatusPending,
+		RunAt:     params.RunAt,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	updatedData := cloneDataset(s.data)
+	if updatedData.Jobs == nil {
+		updatedData.Jobs = make(map[string]Job)
+	}
+	updatedData.Jobs[id] = job
+	if err := s.persistDataset(updatedData); err != nil {
+		return Job{}, err
+	}
+	s.data = updatedData
+	return cloneJob(job), nil
+}
+
+// GetJob returns a job by id.
+func (s *Storage) GetJob(id string) (Job, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	job, ok := s.data.Jobs[id]
+	if !ok {
+		return Job{}, false
+	}
+	return cloneJob(job), true
+}
+
+// ClaimDueJobs leases up to limit due jobs to workerID, oldest run time
+// first. Jobs stay claimed for lease; if the worker has not completed or
+// failed a job by then, the next claim hands it to another worker.
+func (s *Storage) ClaimDueJobs(workerID string, limit int, lease time.Duration) ([]Job, error) {
+	workerID = strings.TrimSpace(workerID)
+	if workerID == "" {
+		return nil, errors.New("worker id is required")
+	}
+	if lease <= 0 {
+		return nil, errors.New("job lease must be positive")
+	}
+	if limit <= 0 {
+		return nil, nil
+	}
+	now := time.Now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	due := make([]Job, 0)
+	for _, job := range s.data.Jobs {
+		if jobClaimable(job, now) {
+			due = append(due, job)
+		}
+	}
+	if len(due) == 0 {
+		return nil, nil
+	}
+	sort.Slice(due, func(i, j int) bool {
+		if due[i].RunAt.Equal(due[j].RunAt) {
+			return due[i].ID < due[j].ID
+		}
+		return due[i].RunAt.Before(due[j].RunAt)
+	})
+	if len(due) > limit {
+		due = due[:limit]
+	}
+
+	updatedData := cloneDataset(s.data)
+	lockedUntil := now.Add(lease)
+	claimed := make([]Job, 0, len(due))
+	for _, job := range due {
+		job.Status = JobStatusRunning
+		job.Attempts++
+		job.LockedBy = workerID
+		until := lockedUntil
+		job.LockedUntil = &until
+		job.UpdatedAt = now
+		updatedData.Jobs[job.ID] = job
+		claimed = append(claimed, cloneJob(job))
+	}
+	if err := s.persistDataset(updatedData); err != nil {
+		return nil, err
+	}
+	s.data = updatedData
+	return claimed, nil
+}
+
+// claimedJob returns the job if workerID still holds its claim.
+func claimedJob(data dataset, id, workerID string) (Job, error) {
+	job, ok := data.Jobs[id]
+	if !ok {
+		return Job{}, notFound("job", id)
+	}
+	if job.Status != JobStatusRunning || job.LockedBy != workerID {
+		return Job{}, ErrJobNotClaimed
+	}
+	return job, nil
+}
+
+// CompleteJob removes a job workerID finished successfully.
+func (s *Storage) CompleteJob(id, workerID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, err := claimedJob(s.data, id, workerID); err != nil {
+		return err
+	}
+	updatedData := cloneDataset(s.data)
+	delete(updatedData.Jobs, id)
+	if err := s.persistDataset(updatedData); err != nil {
+		return err
+	}
+	s.data = updatedData
+	return nil
+}
+
+// FailJob releases a job workerID could not finish and records why. The job
+// runs again at retryAt; a zero retryAt marks it failed for good.
+func (s *Storage) FailJob(id, workerID, message string, retryAt time.Time) (Job, error) {
+	now := time.Now().UTC()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	job, err := claimedJob(s.data, id, workerID)
+	if err != nil {
+		return Job{}, err
+	}
+	job.LastError = strings.TrimSpace(message)
+	job.LockedBy = ""
+	job.LockedUntil = nil
+	job.UpdatedAt = now
+	if retryAt.IsZero() {
+		job.Status = JobStatusFailed
+	} else {
+		job.Status = JobStatusPending
+		job.RunAt = retryAt.UTC()
+	}
+	updatedData := cloneDataset(s.data)
+	updatedData.Jobs[id] = job
+	if err := s.persistDataset(updatedData); err != nil {
+		return Job{}, err
+	}
+	s.data = updatedData
+	return cloneJob(job), nil
+}
@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/ingest"
+	"bitriver-live/internal/models"
+)
+
+const (
+	// DefaultLiveClipSeconds is the clip length used when a viewer does not
+	// pick one.
+	DefaultLiveClipSeconds = 30
+	// MaxLiveClipSeconds caps live clips at the transcoder's default rolling
+	// buffer.
+	MaxLiveClipSeconds = 60
+)
+
+// liveClipTimeout is the least time a clip request waits for the
+// transcoder. Cutting a clip remuxes up to a minute of video, which can take
+// longer than the ingest timeout allows.
+const liveClipTimeout = 30 * time.Second
+
+// normalizeLiveClipParams validates params and fills in the default
+// duration.
+func normalizeLiveClipParams(params LiveClipParams) (LiveClipParams, error) {
+	params.UserID = strings.TrimSpace(params.UserID)
+	params.Title = strings.TrimSpace(params.Title)
+	if params.UserID == "" {
+		return LiveClipParams{}, fmt.Errorf("user id is required")
+	}
+	if params.Title == "" {
+		return LiveClipParams{}, invalid("title", "title is required")
+	}
+	if params.DurationSeconds == 0 {
+		params.DurationSeconds = DefaultLiveClipSeconds
+	}
+	if params.DurationSeconds < 0 || params.DurationSeconds > MaxLiveClipSeconds {
+		return LiveClipParams{}, fmt.Errorf("durationSeconds must be between 1 and %d", MaxLiveClipSeconds)
+	}
+	return params, nil
+}
+
+// captureSessionClip asks the ingest controller to cut a clip from the first
+// of jobIDs that has buffered output. Controllers that cannot cut clips
+// report ingest.ErrClipUnavailable.
+func captureSessionClip(controller ingest.Controller, timeout time.Duration, jobIDs []string, durationSeconds int) (ingest.LiveClip, error) {
+	if controller == nil {
+		return ingest.LiveClip{}, ErrIngestControllerUnavailable
+	}
+	clipper, ok := controller.(ingest.LiveClipper)
+	if !ok || len(jobIDs) == 0 {
+		return ingest.LiveClip{}, ingest.ErrClipUnavailable
+	}
+	timeout = normalizeIngestTimeout(timeout)
+	if timeout < liveClipTimeout {
+		timeout = liveClipTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	for _, jobID := range jobIDs {
+		clip, err := clipper.CaptureClip(ctx, jobID, durationSeconds)
+		if err == nil {
+			return clip, nil
+		}
+		if !errors.Is(err, ingest.ErrClipUnavailable) {
+			return ingest.LiveClip{}, fmt.Errorf("capture clip: %w", err)
+		}
+	}
+	return ingest.LiveClip{}, ingest.ErrClipUnavailable
+}
+
+// newLiveClip builds the clip row for a clip cut at now. Start and end are
+// offsets into the session, so they line up with the recording StopStream
+// later links the clip to.
+func newLiveClip(session models.StreamSession, params LiveClipParams, clip ingest.LiveClip, now time.Time) (models.ClipExport, error) {
+	id, err := generateID()
+	if err != nil {
+		return models.ClipExport{}, err
+	}
+	duration := clip.DurationSeconds
+	if duration <= 0 {
+		duration = params.DurationSeconds
+	}
+	end := int(now.Sub(session.StartedAt) / time.Second)
+	if end < duration {
+		end = duration
+	}
+	completed := now
+	return models.ClipExport{
+		ID:           id,
+		ChannelID:    session.ChannelID,
+		SessionID:    session.ID,
+		Title:        params.Title,
+		StartSeconds: end - duration,
+		EndSeconds:   end,
+		Status:       "ready",
+		PlaybackURL:  clip.PlaybackURL,
+		CreatedAt:    now,
+		CompletedAt:  &completed,
+		CreatedBy:    params.UserID,
+	}, nil
+}
+
+// CreateLiveClip cuts the last params.DurationSeconds of the channel's live
+// stream and records the clip against the current session. If the stream
+// stopped while the clip was cut, the clip is linked to the session's
+// recording straight away.
+func (s *Storage) CreateLiveClip(channelID string, params LiveClipParams) (models.ClipExport, error) {
+	params, err := normalizeLiveClipParams(params)
+	if err != nil {
+		return models.ClipExport{}, err
+	}
+
+	s.mu.RLock()
+	channel, ok := s.data.Channels[channelID]
+	if !ok {
+		s.mu.RUnlock()
+		return models.ClipExport{}, notFound("channel", channelID)
+	}
+	if _, ok := s.data.Users[params.UserID]; !ok {
+		s.mu.RUnlock()
+		return models.ClipExport{}, notFound("user", params.UserID)
+	}
+	if channel.CurrentSessionID == nil {
+		s.mu.RUnlock()
+		return models.ClipExport{}, ErrChannelNotLive
+	}
+	session, ok := s.data.StreamSessions[*channel.CurrentSessionID]
+	s.mu.RUnlock()
+	if !ok {
+		return models.ClipExport{}, fmt.Errorf("session %s missing", *channel.CurrentSessionID)
+	}
+
+	captured, err := captureSessionClip(s.ingestController, s.ingestTimeout, session.IngestJobIDs, params.DurationSeconds)
+	if err != nil {
+		return models.ClipExport{}, err
+	}
+	clip, err := newLiveClip(session, params, captured, time.Now().UTC())
+	if err != nil {
+		return models.ClipExport{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, recording := range s.data.Recordings {
+		if recording.SessionID == session.ID {
+			clip.RecordingID = recording.ID
+			break
+		}
+	}
+	snapshot := cloneDataset(s.data)
+	s.data.ClipExports[clip.ID] = clip
+	if err := s.persist(); err != nil {
+		s.data = snapshot
+		return models.ClipExport{}, err
+	}
+	return cloneClipExport(clip), nil
+}
+
+// linkSessionClipsLocked attaches clips cut while sessionID was live to
+// recordingID and returns the IDs it changed.
+func (s *Storage) linkSessionClipsLocked(sessionID, recordingID string) []string {
+	var linked []string
+	for id, clip := range s.data.ClipExports {
+		if clip.SessionID != sessionID || clip.RecordingID != "" {
+			continue
+		}
+		clip.RecordingID = recordingID
+		s.data.ClipExports[id] = clip
+		linked = append(linked, id)
+	}
+	return linked
+}
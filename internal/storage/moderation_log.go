@@ -0,0 +1,146 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/chat"
+	"bitriver-live/internal/models"
+)
+
+// ModerationHistoryOptions pages a user's moderation history in a channel. A
+// non-positive Limit returns every entry after Offset.
+type ModerationHistoryOptions struct {
+	Limit  int
+	Offset int
+}
+
+// ModerationHistoryPage is one page of moderation log entries, newest first,
+// along with the number of entries across all pages.
+type ModerationHistoryPage struct {
+	Actions []models.ModerationAction
+	Total   int
+}
+
+// moderationActionFromEvent builds the log entry for a chat moderation event.
+// The entry takes the event's ID so a redelivered event maps to the entry it
+// already wrote. It returns false for events that change nothing, such as a
+// timeout without an expiry.
+func moderationActionFromEvent(evt chat.ModerationEvent, occurredAt time.Time) (models.ModerationAction, bool) {
+	action := models.ModerationAction{
+		ID:        strings.TrimSpace(evt.ID),
+		ChannelID: evt.ChannelID,
+		TargetID:  evt.TargetID,
+		ActorID:   strings.TrimSpace(evt.ActorID),
+		Reason:    strings.TrimSpace(evt.Reason),
+		CreatedAt: occurredAt.UTC(),
+	}
+	if action.CreatedAt.IsZero() {
+		action.CreatedAt = time.Now().UTC()
+	}
+	switch evt.Action {
+	case chat.ModerationActionBan:
+		action.Action = models.ModerationActionBan
+	case chat.ModerationActionUnban:
+		action.Action = models.ModerationActionUnban
+	case chat.ModerationActionTimeout:
+		if evt.ExpiresAt == nil {
+			return models.ModerationAction{}, false
+		}
+		action.Action = models.ModerationActionTimeout
+		action.Metadata = map[string]string{"expiresAt": evt.ExpiresAt.UTC().Format(time.RFC3339Nano)}
+	case chat.ModerationActionRemoveTimeout:
+		action.Action = models.ModerationActionRemoveTimeout
+	default:
+		return models.ModerationAction{}, false
+	}
+	return action, true
+}
+
+func deletedMessageAction(message models.ChatMessage, actorID string, now time.Time) models.ModerationAction {
+	return models.ModerationAction{
+		ChannelID: message.ChannelID,
+		TargetID:  message.UserID,
+		ActorID:   strings.TrimSpace(actorID),
+		Action:    models.ModerationActionDeleteMessage,
+		Metadata:  map[string]string{"messageId": message.ID, "content": message.Content},
+		CreatedAt: now,
+	}
+}
+
+func resolvedReportAction(report models.ChatReport) models.ModerationAction {
+	metadata := map[string]string{"reportId": report.ID, "reportReason": report.Reason}
+	if report.MessageID != "" {
+		metadata["messageId"] = report.MessageID
+	}
+	action := models.ModerationAction{
+		ChannelID: report.ChannelID,
+		TargetID:  report.TargetID,
+		ActorID:   report.ResolverID,
+		Action:    models.ModerationActionResolveReport,
+		Reason:    report.Resolution,
+		Metadata:  metadata,
+		CreatedAt: time.Now().UTC(),
+	}
+	if report.ResolvedAt != nil {
+		action.CreatedAt = *report.ResolvedAt
+	}
+	return action
+}
+
+// appendModerationActionLocked adds the entry to the log, assigning an ID
+// when it has none, and returns the ID it stored so callers can roll the
+// entry back. An entry whose ID is already logged is dropped and the empty
+// ID returned. Callers must hold s.mu for writing.
+func (s *Storage) appendModerationActionLocked(action models.ModerationAction) (string, error) {
+	if s.data.ModerationActions == nil {
+		s.data.ModerationActions = make(map[string]models.ModerationAction)
+	}
+	if action.ID == "" {
+		id, err := generateID()
+		if err != nil {
+			return "", err
+		}
+		action.ID = id
+	}
+	if _, logged := s.data.ModerationActions[action.ID]; logged {
+		return "", nil
+	}
+	s.data.ModerationActions[action.ID] = action
+	return action.ID, nil
+}
+
+// ListModerationHistory pages the moderation log entries about the user in
+// the channel, newest first.
+func (s *Storage) ListModerationHistory(channelID, targetID string, opts ModerationHistoryOptions) (ModerationHistoryPage, error) {
+	s.mu.RLock()
+	actions := make([]models.ModerationAction, 0)
+	for _, action := range s.data.ModerationActions {
+		if action.ChannelID != channelID || action.TargetID != targetID {
+			continue
+		}
+		actions = append(actions, cloneModerationAction(action))
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(actions, func(i, j int) bool {
+		if actions[i].CreatedAt.Equal(actions[j].CreatedAt) {
+			return actions[i].ID > actions[j].ID
+		}
+		return actions[i].CreatedAt.After(actions[j].CreatedAt)
+	})
+	start, end := pageBounds(len(actions), UserListOptions{Limit: opts.Limit, Offset: opts.Offset})
+	return ModerationHistoryPage{Actions: actions[start:end], Total: len(actions)}, nil
+}
+
+func cloneModerationAction(action models.ModerationAction) models.ModerationAction {
+	if action.Metadata != nil {
+		metadata := make(map[string]string, len(action.Metadata))
+		for key, value := range action.Metadata {
+			metadata[key] = value
+		}
+		action.Metadata = metadata
+	}
+	return action
+}
@@ -1,7 +1,6 @@
 package storage
 
 import (
-	"errors"
 	"fmt"
 	"sort"
 	"strings"
@@ -17,10 +16,10 @@ func (s *Storage) CreateTip(params CreateTipParams) (models.Tip, error) {
 	defer s.mu.Unlock()
 
 	if _, ok := s.data.Channels[params.ChannelID]; !ok {
-		return models.Tip{}, fmt.Errorf("channel %s not found", params.ChannelID)
+		return models.Tip{}, notFound("channel", params.ChannelID)
 	}
 	if _, ok := s.data.Users[params.FromUserID]; !ok {
-		return models.Tip{}, fmt.Errorf("user %s not found", params.FromUserID)
+		return models.Tip{}, notFound("user", params.FromUserID)
 	}
 	amount := params.Amount
 	if amount.MinorUnits() <= 0 {
@@ -32,7 +31,7 @@ func (s *Storage) CreateTip(params CreateTipParams) (models.Tip, error) {
 	}
 	provider := strings.ToLower(strings.TrimSpace(params.Provider))
 	if provider == "" {
-		return models.Tip{}, fmt.Errorf("provider is required")
+		return models.Tip{}, invalid("provider", "provider is required")
 	}
 	reference := strings.TrimSpace(params.Reference)
 	if reference == "" {
@@ -50,7 +49,7 @@ func (s *Storage) CreateTip(params CreateTipParams) (models.Tip, error) {
 		return models.Tip{}, fmt.Errorf("message exceeds %d characters", MaxTipMessageLength)
 	}
 	if s.tipExists(provider, reference) {
-		return models.Tip{}, errors.New(duplicateTipReferenceError)
+		return models.Tip{}, conflict("reference", "tip reference %s/%s already exists", provider, reference)
 	}
 	id, err := generateID()
 	if err != nil {
@@ -100,7 +99,7 @@ func (s *Storage) ListTips(channelID string, limit int) ([]models.Tip, error) {
 	defer s.mu.RUnlock()
 
 	if _, ok := s.data.Channels[channelID]; !ok {
-		return nil, fmt.Errorf("channel %s not found", channelID)
+		return nil, notFound("channel", channelID)
 	}
 	tips := make([]models.Tip, 0)
 	for _, tip := range s.data.Tips {
@@ -109,7 +108,10 @@ func (s *Storage) ListTips(channelID string, limit int) ([]models.Tip, error) {
 		}
 	}
 	sort.Slice(tips, func(i, j int) bool {
-		return tips[i].CreatedAt.After(tips[j].CreatedAt)
+		if !tips[i].CreatedAt.Equal(tips[j].CreatedAt) {
+			return tips[i].CreatedAt.After(tips[j].CreatedAt)
+		}
+		return tips[i].ID < tips[j].ID
 	})
 	if limit > 0 && len(tips) > limit {
 		tips = tips[:limit]
@@ -123,10 +125,10 @@ func (s *Storage) CreateSubscription(params CreateSubscriptionParams) (models.Su
 	defer s.mu.Unlock()
 
 	if _, ok := s.data.Channels[params.ChannelID]; !ok {
-		return models.Subscription{}, fmt.Errorf("channel %s not found", params.ChannelID)
+		return models.Subscription{}, notFound("channel", params.ChannelID)
 	}
 	if _, ok := s.data.Users[params.UserID]; !ok {
-		return models.Subscription{}, fmt.Errorf("user %s not found", params.UserID)
+		return models.Subscription{}, notFound("user", params.UserID)
 	}
 	if params.Duration <= 0 {
 		return models.Subscription{}, fmt.Errorf("duration must be positive")
@@ -145,7 +147,18 @@ func (s *Storage) CreateSubscription(params CreateSubscriptionParams) (models.Su
 	}
 	provider := strings.ToLower(strings.TrimSpace(params.Provider))
 	if provider == "" {
-		return models.Subscription{}, fmt.Errorf("provider is required")
+		return models.Subscription{}, invalid("provider", "provider is required")
+	}
+	gifterID := strings.TrimSpace(params.GifterID)
+	if params.IsGift {
+		if gifterID == "" {
+			return models.Subscription{}, fmt.Errorf("gifter is required for gifted subscriptions")
+		}
+		if _, ok := s.data.Users[gifterID]; !ok {
+			return models.Subscription{}, notFound("user", gifterID)
+		}
+	} else {
+		gifterID = ""
 	}
 	reference := strings.TrimSpace(params.Reference)
 	if reference == "" {
@@ -153,7 +166,7 @@ func (s *Storage) CreateSubscription(params CreateSubscriptionParams) (models.Su
 	}
 	for _, existing := range s.data.Subscriptions {
 		if existing.Provider == provider && existing.Reference == reference {
-			return models.Subscription{}, fmt.Errorf("subscription reference %s/%s already exists", provider, reference)
+			return models.Subscription{}, conflict("reference", "subscription reference %s/%s already exists", provider, reference)
 		}
 	}
 	id, err := generateID()
@@ -176,6 +189,8 @@ func (s *Storage) CreateSubscription(params CreateSubscriptionParams) (models.Su
 		AutoRenew:         params.AutoRenew,
 		Status:            "active",
 		ExternalReference: strings.TrimSpace(params.ExternalReference),
+		GifterID:          gifterID,
+		IsGift:            params.IsGift,
 	}
 	if s.data.Subscriptions == nil {
 		s.data.Subscriptions = make(map[string]models.Subscription)
@@ -188,13 +203,284 @@ func (s *Storage) CreateSubscription(params CreateSubscriptionParams) (models.Su
 	return subscription, nil
 }
 
+// normalizeGiftSubscriptionsParams validates a gift batch and returns the
+// cleaned tier, provider, currency, reference base, and de-duplicated
+// recipients shared by both repositories.
+func normalizeGiftSubscriptionsParams(params GiftSubscriptionsParams) (GiftSubscriptionsParams, error) {
+	if params.Duration <= 0 {
+		return GiftSubscriptionsParams{}, fmt.Errorf("duration must be positive")
+	}
+	if params.Amount.MinorUnits() < 0 {
+		return GiftSubscriptionsParams{}, fmt.Errorf("amount cannot be negative")
+	}
+	params.GifterID = strings.TrimSpace(params.GifterID)
+	if params.GifterID == "" {
+		return GiftSubscriptionsParams{}, fmt.Errorf("gifter is required")
+	}
+	params.Currency = strings.ToUpper(strings.TrimSpace(params.Currency))
+	if params.Currency == "" {
+		return GiftSubscriptionsParams{}, fmt.Errorf("currency is required")
+	}
+	params.Tier = strings.TrimSpace(params.Tier)
+	if params.Tier == "" {
+		params.Tier = "supporter"
+	}
+	params.Provider = strings.ToLower(strings.TrimSpace(params.Provider))
+	if params.Provider == "" {
+		return GiftSubscriptionsParams{}, invalid("provider", "provider is required")
+	}
+	params.Reference = strings.TrimSpace(params.Reference)
+	if params.Reference == "" {
+		params.Reference = fmt.Sprintf("gift-%d", time.Now().UnixNano())
+	}
+	recipients := make([]string, 0, len(params.RecipientIDs))
+	seen := make(map[string]struct{}, len(params.RecipientIDs))
+	for _, id := range params.RecipientIDs {
+		id = strings.TrimSpace(id)
+		if id == "" {
+			continue
+		}
+		if id == params.GifterID {
+			return GiftSubscriptionsParams{}, fmt.Errorf("cannot gift a subscription to yourself")
+		}
+		if _, dup := seen[id]; dup {
+			return GiftSubscriptionsParams{}, fmt.Errorf("recipient %s listed more than once", id)
+		}
+		seen[id] = struct{}{}
+		recipients = append(recipients, id)
+	}
+	if len(recipients) == 0 {
+		return GiftSubscriptionsParams{}, fmt.Errorf("at least one recipient is required")
+	}
+	if len(recipients) > MaxGiftSubscriptionCount {
+		return GiftSubscriptionsParams{}, fmt.Errorf("cannot gift more than %d subscriptions at once", MaxGiftSubscriptionCount)
+	}
+	params.RecipientIDs = recipients
+	return params, nil
+}
+
+// giftSubscriptionReference derives the reference for the n-th gifted
+// subscription (1-based) so each row stays unique per provider.
+func giftSubscriptionReference(base string, n int) string {
+	return fmt.Sprintf("%s-%d", base, n)
+}
+
+// GiftSubscriptions creates or extends a subscription for every recipient.
+// Either every recipient is credited or none are.
+func (s *Storage) GiftSubscriptions(params GiftSubscriptionsParams) ([]models.Subscription, error) {
+	normalized, err := normalizeGiftSubscriptionsParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.data.Channels[normalized.ChannelID]; !ok {
+		return nil, notFound("channel", normalized.ChannelID)
+	}
+	if _, ok := s.data.Users[normalized.GifterID]; !ok {
+		return nil, notFound("user", normalized.GifterID)
+	}
+	for _, recipient := range normalized.RecipientIDs {
+		if _, ok := s.data.Users[recipient]; !ok {
+			return nil, notFound("user", recipient)
+		}
+	}
+
+	now := time.Now().UTC()
+	results := make([]models.Subscription, 0, len(normalized.RecipientIDs))
+	for i, recipient := range normalized.RecipientIDs {
+		if existing, ok := s.activeSubscriptionLocked(normalized.ChannelID, recipient, now); ok {
+			existing.ExpiresAt = existing.ExpiresAt.Add(normalized.Duration)
+			results = append(results, existing)
+			continue
+		}
+		reference := giftSubscriptionReference(normalized.Reference, i+1)
+		for _, existing := range s.data.Subscriptions {
+			if existing.Provider == normalized.Provider && existing.Reference == reference {
+				return nil, conflict("reference", "subscription reference %s/%s already exists", normalized.Provider, reference)
+			}
+		}
+		id, err := generateID()
+		if err != nil {
+			return nil, err
+		}
+		results = append(results, models.Subscription{
+			ID:        id,
+			ChannelID: normalized.ChannelID,
+			UserID:    recipient,
+			Tier:      normalized.Tier,
+			Provider:  normalized.Provider,
+			Reference: reference,
+			Amount:    normalized.Amount,
+			Currency:  normalized.Currency,
+			StartedAt: now,
+			ExpiresAt: now.Add(normalized.Duration),
+			Status:    "active",
+			GifterID:  normalized.GifterID,
+			IsGift:    true,
+		})
+	}
+
+	if s.data.Subscriptions == nil {
+		s.data.Subscriptions = make(map[string]models.Subscription)
+	}
+	previous := make(map[string]models.Subscription, len(results))
+	for _, sub := range results {
+		if existing, ok := s.data.Subscriptions[sub.ID]; ok {
+			previous[sub.ID] = existing
+		}
+		s.data.Subscriptions[sub.ID] = sub
+	}
+	if err := s.persist(); err != nil {
+		for _, sub := range results {
+			if existing, ok := previous[sub.ID]; ok {
+				s.data.Subscriptions[sub.ID] = existing
+			} else {
+				delete(s.data.Subscriptions, sub.ID)
+			}
+		}
+		return nil, err
+	}
+	return results, nil
+}
+
+// activeSubscriptionLocked returns the recipient's active subscription to the
+// channel with the latest expiry. Callers must hold s.mu.
+func (s *Storage) activeSubscriptionLocked(channelID, userID string, now time.Time) (models.Subscription, bool) {
+	var (
+		found models.Subscription
+		ok    bool
+	)
+	for _, sub := range s.data.Subscriptions {
+		if sub.ChannelID != channelID || sub.UserID != userID || !strings.EqualFold(sub.Status, "active") {
+			continue
+		}
+		if !sub.ExpiresAt.After(now) {
+			continue
+		}
+		if !ok || sub.ExpiresAt.After(found.ExpiresAt) {
+			found = sub
+			ok = true
+		}
+	}
+	return found, ok
+}
+
+// subscriberMonth is the length of a month when counting cumulative
+// subscribed months.
+const subscriberMonth = 30 * 24 * time.Hour
+
+// subscriptionCounts reports whether sub counts toward a subscriber's
+// standing: active subscriptions and cancelled ones, which run until they
+// expire.
+func subscriptionCounts(sub models.Subscription) bool {
+	return strings.EqualFold(sub.Status, "active") || strings.EqualFold(sub.Status, "cancelled")
+}
+
+// subscriptionActive reports whether sub makes its holder a subscriber at
+// now.
+func subscriptionActive(sub models.Subscription, now time.Time) bool {
+	return subscriptionCounts(sub) && sub.ExpiresAt.After(now)
+}
+
+// subscriptionStanding summarises subs, the user's subscriptions to one
+// channel, at now. The time covered by the subscriptions up to now is merged,
+// so overlapping subscriptions such as a gift during a paid month count once,
+// and summed across gaps. A month that has begun counts in full, so a new
+// subscriber has one month.
+func subscriptionStanding(channelID, userID string, subs []models.Subscription, now time.Time) SubscriptionStanding {
+	standing := SubscriptionStanding{ChannelID: channelID, UserID: userID}
+	type span struct{ start, end time.Time }
+	spans := make([]span, 0, len(subs))
+	for _, sub := range subs {
+		if !subscriptionCounts(sub) {
+			continue
+		}
+		if sub.ExpiresAt.After(now) {
+			standing.Active = true
+			if standing.ExpiresAt == nil || sub.ExpiresAt.After(*standing.ExpiresAt) {
+				expires := sub.ExpiresAt
+				standing.ExpiresAt = &expires
+			}
+		}
+		end := sub.ExpiresAt
+		if end.After(now) {
+			end = now
+		}
+		if end.After(sub.StartedAt) {
+			spans = append(spans, span{start: sub.StartedAt, end: end})
+		}
+	}
+	sort.Slice(spans, func(i, j int) bool { return spans[i].start.Before(spans[j].start) })
+	var (
+		total   time.Duration
+		current span
+	)
+	for i, next := range spans {
+		switch {
+		case i == 0:
+			current = next
+		case next.start.After(current.end):
+			total += current.end.Sub(current.start)
+			current = next
+		case next.end.After(current.end):
+			current.end = next.end
+		}
+	}
+	if len(spans) > 0 {
+		total += current.end.Sub(current.start)
+	}
+	standing.Months = int((total + subscriberMonth - 1) / subscriberMonth)
+	if standing.Active && standing.Months == 0 {
+		standing.Months = 1
+	}
+	return standing
+}
+
+// subscriptionsForLocked returns the user's subscriptions to the channel.
+// Callers must hold s.mu.
+func (s *Storage) subscriptionsForLocked(channelID, userID string) []models.Subscription {
+	subs := make([]models.Subscription, 0)
+	for _, sub := range s.data.Subscriptions {
+		if sub.ChannelID == channelID && sub.UserID == userID {
+			subs = append(subs, sub)
+		}
+	}
+	return subs
+}
+
+// subscribedLocked reports whether the user is a subscriber of the channel
+// at now. Callers must hold s.mu.
+func (s *Storage) subscribedLocked(channelID, userID string, now time.Time) bool {
+	for _, sub := range s.data.Subscriptions {
+		if sub.ChannelID == channelID && sub.UserID == userID && subscriptionActive(sub, now) {
+			return true
+		}
+	}
+	return false
+}
+
+// SubscriptionStanding reports whether the user is subscribed to the channel
+// and for how many months in total.
+func (s *Storage) SubscriptionStanding(channelID, userID string) (SubscriptionStanding, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.data.Channels[channelID]; !ok {
+		return SubscriptionStanding{}, notFound("channel", channelID)
+	}
+	return subscriptionStanding(channelID, userID, s.subscriptionsForLocked(channelID, userID), time.Now().UTC()), nil
+}
+
 // ListSubscriptions lists subscriptions for a channel.
 func (s *Storage) ListSubscriptions(channelID string, includeInactive bool) ([]models.Subscription, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	if _, ok := s.data.Channels[channelID]; !ok {
-		return nil, fmt.Errorf("channel %s not found", channelID)
+		return nil, notFound("channel", channelID)
 	}
 	subs := make([]models.Subscription, 0)
 	for _, sub := range s.data.Subscriptions {
@@ -230,13 +516,13 @@ func (s *Storage) CancelSubscription(id, cancelledBy, reason string) (models.Sub
 
 	subscription, ok := s.data.Subscriptions[id]
 	if !ok {
-		return models.Subscription{}, fmt.Errorf("subscription %s not found", id)
+		return models.Subscription{}, notFound("subscription", id)
 	}
 	if subscription.Status == "cancelled" {
 		return subscription, nil
 	}
 	if _, ok := s.data.Users[cancelledBy]; !ok {
-		return models.Subscription{}, fmt.Errorf("user %s not found", cancelledBy)
+		return models.Subscription{}, notFound("user", cancelledBy)
 	}
 	now := time.Now().UTC()
 	subscription.Status = "cancelled"
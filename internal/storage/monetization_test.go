@@ -38,10 +38,12 @@ func TestStorageTipReferenceUniqueness(t *testing.T) {
 	if _, err := store.CreateTip(params); err != nil {
 		t.Fatalf("create tip: %v", err)
 	}
-	if _, err := store.CreateTip(params); err == nil {
+	_, err = store.CreateTip(params)
+	if err == nil {
 		t.Fatal("expected duplicate tip creation to fail")
-	} else if err.Error() != duplicateTipReferenceError {
-		t.Fatalf("unexpected duplicate error: %v", err)
+	}
+	if got, want := err.Error(), "tip reference stripe/dup-ref already exists"; got != want {
+		t.Fatalf("unexpected error: got %q want %q", got, want)
 	}
 }
 
@@ -49,6 +51,10 @@ func TestCreateSubscriptionAndCancel(t *testing.T) {
 	RunRepositorySubscriptionsLifecycle(t, jsonRepositoryFactory)
 }
 
+func TestGiftSubscriptions(t *testing.T) {
+	RunRepositorySubscriptionGifts(t, jsonRepositoryFactory)
+}
+
 func TestSubscriptionReferenceUniquenessJSON(t *testing.T) {
 	store := newTestStore(t)
 
@@ -91,6 +97,41 @@ func TestRepositoryMonetizationPrecision(t *testing.T) {
 	RunRepositoryMonetizationPrecision(t, jsonRepositoryFactory)
 }
 
+func TestSubscriptionStandingCountsMonths(t *testing.T) {
+	now := time.Date(2026, 6, 1, 0, 0, 0, 0, time.UTC)
+	day := 24 * time.Hour
+	sub := func(status string, start, end time.Duration) models.Subscription {
+		return models.Subscription{Status: status, StartedAt: now.Add(start), ExpiresAt: now.Add(end)}
+	}
+
+	tests := []struct {
+		name   string
+		subs   []models.Subscription
+		active bool
+		months int
+	}{
+		{name: "none", subs: nil},
+		{name: "new subscriber", subs: []models.Subscription{sub("active", -time.Hour, 30*day)}, active: true, months: 1},
+		{name: "partial month rounds up", subs: []models.Subscription{sub("active", -31*day, 29*day)}, active: true, months: 2},
+		{name: "gaps are summed", subs: []models.Subscription{sub("active", -200*day, -170*day), sub("active", -100*day, -70*day), sub("active", -10*day, 20*day)}, active: true, months: 3},
+		{name: "overlaps count once", subs: []models.Subscription{sub("active", -60*day, 0), sub("active", -45*day, -15*day)}, months: 2},
+		{name: "cancelled runs until expiry", subs: []models.Subscription{sub("cancelled", -5*day, 25*day)}, active: true, months: 1},
+		{name: "expired keeps its months", subs: []models.Subscription{sub("active", -90*day, -30*day)}, months: 2},
+		{name: "refunded is ignored", subs: []models.Subscription{sub("refunded", -90*day, 30*day)}},
+	}
+	for _, tc := range tests {
+		t.Run(tc.name, func(t *testing.T) {
+			standing := subscriptionStanding("channel", "user", tc.subs, now)
+			if standing.Active != tc.active || standing.Months != tc.months {
+				t.Fatalf("expected active=%v months=%d, got %+v", tc.active, tc.months, standing)
+			}
+			if standing.Active != (standing.ExpiresAt != nil) {
+				t.Fatalf("expected an expiry only while active, got %+v", standing)
+			}
+		})
+	}
+}
+
 func TestUpsertProfileCreatesProfile(t *testing.T) {
 	store := newTestStore(t)
 	owner, err := store.CreateUser(CreateUserParams{
@@ -118,7 +159,7 @@ func TestUpsertProfileCreatesProfile(t *testing.T) {
 	banner := "https://cdn.example.com/banner.png"
 	featured := channel.ID
 	topFriends := []string{friend.ID}
-	donation := []models.CryptoAddress{{Currency: "eth", Address: "0xabc", Note: "Primary"}}
+	donation := []models.CryptoAddress{{Currency: "eth", Address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed", Note: "Primary"}}
 
 	profile, err := store.UpsertProfile(owner.ID, ProfileUpdate{
 		Bio:               &bio,
@@ -161,7 +202,7 @@ func TestUpsertProfileCreatesProfile(t *testing.T) {
 
 	// second update clears top friends and replaces donation details
 	topFriends = []string{}
-	donation = []models.CryptoAddress{{Currency: "btc", Address: "bc1xyz"}}
+	donation = []models.CryptoAddress{{Currency: "btc", Address: "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa"}}
 	updated, err := store.UpsertProfile(owner.ID, ProfileUpdate{
 		TopFriends:        &topFriends,
 		DonationAddresses: &donation,
@@ -193,7 +234,7 @@ func TestUpsertProfileDonationValidation(t *testing.T) {
 		t.Fatalf("CreateUser owner: %v", err)
 	}
 
-	valid := []models.CryptoAddress{{Currency: "eth", Address: "0xabc123"}}
+	valid := []models.CryptoAddress{{Currency: "eth", Address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"}}
 	if _, err := store.UpsertProfile(owner.ID, ProfileUpdate{DonationAddresses: &valid}); err != nil {
 		t.Fatalf("expected valid donation addresses to succeed: %v", err)
 	}
@@ -204,7 +245,7 @@ func TestUpsertProfileDonationValidation(t *testing.T) {
 	}{
 		{
 			name:     "invalid currency",
-			donation: []models.CryptoAddress{{Currency: "et1", Address: "0xabc123"}},
+			donation: []models.CryptoAddress{{Currency: "et1", Address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"}},
 		},
 		{
 			name:     "too short",
@@ -214,6 +255,41 @@ func TestUpsertProfileDonationValidation(t *testing.T) {
 			name:     "invalid characters",
 			donation: []models.CryptoAddress{{Currency: "ETH", Address: "bad address"}},
 		},
+		{
+			name:     "unsupported currency",
+			donation: []models.CryptoAddress{{Currency: "DOGE", Address: "DH5yaieqoZN36fDVciNyRueRGvGLR3mr7L"}},
+		},
+		{
+			name:     "bitcoin base58check checksum",
+			donation: []models.CryptoAddress{{Currency: "BTC", Address: "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNb"}},
+		},
+		{
+			name:     "bitcoin bech32 checksum",
+			donation: []models.CryptoAddress{{Currency: "BTC", Address: "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5"}},
+		},
+		{
+			name:     "ethereum EIP-55 checksum",
+			donation: []models.CryptoAddress{{Currency: "ETH", Address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD"}},
+		},
+		{
+			name:     "monero prefix",
+			donation: []models.CryptoAddress{{Currency: "XMR", Address: "14AFFq5kSiGBoZ4NMDwYtN18obc8AemS33DBLWs3H7otXft3XjrpDtQGv7SqSsaBYBb98uNbr2VBBEt7f2wfn3RVGQBEP3A"}},
+		},
+		{
+			name:     "monero length",
+			donation: []models.CryptoAddress{{Currency: "XMR", Address: "44AFFq5kSiGBoZ4NMDwYtN18obc8AemS33DBLWs3H7otXft3XjrpDtQGv7SqSsaBYBb98uNbr2VBBEt7f2wfn3RVGQBEP3"}},
+		},
+		{
+			name: "duplicate address",
+			donation: []models.CryptoAddress{
+				{Currency: "ETH", Address: "0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaed"},
+				{Currency: "ETH", Address: "0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed"},
+			},
+		},
+		{
+			name:     "too many addresses",
+			donation: tooManyDonationAddresses(),
+		},
 	}
 
 	for _, tc := range testCases {
@@ -226,6 +302,42 @@ func TestUpsertProfileDonationValidation(t *testing.T) {
 	}
 }
 
+func tooManyDonationAddresses() []models.CryptoAddress {
+	addresses := make([]models.CryptoAddress, 0, MaxDonationAddresses+1)
+	for i := 0; i <= MaxDonationAddresses; i++ {
+		addresses = append(addresses, models.CryptoAddress{Currency: "ETH", Address: fmt.Sprintf("0x%040x", i+1)})
+	}
+	return addresses
+}
+
+func TestUpsertProfileNormalizesDonationAddresses(t *testing.T) {
+	store := newTestStore(t)
+	owner, err := store.CreateUser(CreateUserParams{DisplayName: "Creator", Email: "creator@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser owner: %v", err)
+	}
+
+	addresses := []models.CryptoAddress{
+		{Currency: " eth ", Address: "0xfb6916095ca1df60bb79ce92ce3ea74c37c5d359"},
+		{Currency: "btc", Address: "BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4"},
+		{Currency: "xmr", Address: "44AFFq5kSiGBoZ4NMDwYtN18obc8AemS33DBLWs3H7otXft3XjrpDtQGv7SqSsaBYBb98uNbr2VBBEt7f2wfn3RVGQBEP3A"},
+	}
+	profile, err := store.UpsertProfile(owner.ID, ProfileUpdate{DonationAddresses: &addresses})
+	if err != nil {
+		t.Fatalf("UpsertProfile: %v", err)
+	}
+	want := []models.CryptoAddress{
+		{Currency: models.CryptoCurrencyETH, Address: "0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359"},
+		{Currency: models.CryptoCurrencyBTC, Address: "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4"},
+		{Currency: models.CryptoCurrencyXMR, Address: addresses[2].Address},
+	}
+	for i, addr := range profile.DonationAddresses {
+		if addr.Currency != want[i].Currency || addr.Address != want[i].Address {
+			t.Fatalf("address %d: expected %s %s, got %s %s", i, want[i].Currency, want[i].Address, addr.Currency, addr.Address)
+		}
+	}
+}
+
 func TestUpsertProfileTopFriendsLimit(t *testing.T) {
 	store := newTestStore(t)
 	owner, err := store.CreateUser(CreateUserParams{
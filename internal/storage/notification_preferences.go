@@ -0,0 +1,95 @@
+package storage
+
+import (
+	"time"
+
+	"bitriver-live/internal/models"
+)
+
+// NotificationDeliveryUpdate changes the delivery methods of one category.
+// Nil fields keep their current value.
+type NotificationDeliveryUpdate struct {
+	InApp *bool
+	Email *bool
+}
+
+// NotificationPreferencesUpdate changes some of a user's notification
+// preferences. Nil categories keep their current settings.
+type NotificationPreferencesUpdate struct {
+	FollowedLive *NotificationDeliveryUpdate
+	Mentions     *NotificationDeliveryUpdate
+	Moderation   *NotificationDeliveryUpdate
+	Account      *NotificationDeliveryUpdate
+}
+
+func (u *NotificationDeliveryUpdate) apply(delivery *models.NotificationDelivery) {
+	if u == nil {
+		return
+	}
+	if u.InApp != nil {
+		delivery.InApp = *u.InApp
+	}
+	if u.Email != nil {
+		delivery.Email = *u.Email
+	}
+}
+
+func (u NotificationPreferencesUpdate) apply(prefs *models.NotificationPreferences) {
+	u.FollowedLive.apply(&prefs.FollowedLive)
+	u.Mentions.apply(&prefs.Mentions)
+	u.Moderation.apply(&prefs.Moderation)
+	u.Account.apply(&prefs.Account)
+}
+
+func cloneNotificationPreferences(prefs models.NotificationPreferences) models.NotificationPreferences {
+	if prefs.UpdatedAt != nil {
+		updated := *prefs.UpdatedAt
+		prefs.UpdatedAt = &updated
+	}
+	return prefs
+}
+
+// GetNotificationPreferences returns the user's notification preferences,
+// falling back to the defaults when they never changed them.
+func (s *Storage) GetNotificationPreferences(userID string) (models.NotificationPreferences, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.data.Users[userID]; !ok {
+		return models.NotificationPreferences{}, notFound("user", userID)
+	}
+	if prefs, ok := s.data.NotificationPreferences[userID]; ok {
+		return cloneNotificationPreferences(prefs), nil
+	}
+	return models.DefaultNotificationPreferences(userID), nil
+}
+
+// UpdateNotificationPreferences applies update on top of the user's current
+// preferences and stores the result.
+func (s *Storage) UpdateNotificationPreferences(userID string, update NotificationPreferencesUpdate) (models.NotificationPreferences, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.data.Users[userID]; !ok {
+		return models.NotificationPreferences{}, notFound("user", userID)
+	}
+	prefs, ok := s.data.NotificationPreferences[userID]
+	if !ok {
+		prefs = models.DefaultNotificationPreferences(userID)
+	}
+	update.apply(&prefs)
+	now := time.Now().UTC()
+	prefs.UpdatedAt = &now
+
+	updatedData := cloneDataset(s.data)
+	if updatedData.NotificationPreferences == nil {
+		updatedData.NotificationPreferences = make(map[string]models.NotificationPreferences)
+	}
+	updatedData.NotificationPreferences[userID] = prefs
+
+	if err := s.persistDataset(updatedData); err != nil {
+		return models.NotificationPreferences{}, err
+	}
+	s.data = updatedData
+	return cloneNotificationPreferences(prefs), nil
+}
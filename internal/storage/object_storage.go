@@ -6,18 +6,43 @@ import (
 	"crypto/hmac"
 	"crypto/sha256"
 	"encoding/hex"
+	"encoding/xml"
+	"errors"
 	"fmt"
+	"io"
 	"net/http"
 	"net/url"
 	"sort"
+	"strconv"
 	"strings"
 	"time"
+
+	"bitriver-live/internal/observability/metrics"
 )
 
+// ErrPresignUnavailable is returned by PresignGet when object storage is
+// disabled or has no credentials to sign with.
+var ErrPresignUnavailable = errors.New("object storage cannot presign urls")
+
 func applyObjectStorageDefaults(cfg ObjectStorageConfig) ObjectStorageConfig {
 	if cfg.RequestTimeout <= 0 {
 		cfg.RequestTimeout = defaultObjectStorageRequestTimeout
 	}
+	if cfg.UploadTimeout <= 0 {
+		cfg.UploadTimeout = defaultObjectStorageUploadTimeout
+	}
+	if cfg.MultipartThreshold <= 0 {
+		cfg.MultipartThreshold = defaultObjectStorageMultipartThreshold
+	}
+	if cfg.MultipartPartSize <= 0 {
+		cfg.MultipartPartSize = defaultObjectStorageMultipartPartSize
+	}
+	if cfg.MaxRetries <= 0 {
+		cfg.MaxRetries = defaultObjectStorageMaxRetries
+	}
+	if cfg.RetryBackoff <= 0 {
+		cfg.RetryBackoff = defaultObjectStorageRetryBackoff
+	}
 	return cfg
 }
 
@@ -28,6 +53,19 @@ func (cfg ObjectStorageConfig) requestTimeout() time.Duration {
 	return cfg.RequestTimeout
 }
 
+// uploadTimeout returns the per-attempt timeout for a payload of the given
+// size. Small metadata writes share the request timeout so a stalled object
+// store fails fast, while large artefacts get the longer upload budget.
+func (cfg ObjectStorageConfig) uploadTimeout(size int) time.Duration {
+	if size <= smallObjectStoragePayload {
+		return cfg.requestTimeout()
+	}
+	if cfg.UploadTimeout <= 0 {
+		return defaultObjectStorageUploadTimeout
+	}
+	return cfg.UploadTimeout
+}
+
 type noopObjectStorageClient struct{}
 
 func (noopObjectStorageClient) Enabled() bool { return false }
@@ -40,6 +78,10 @@ func (noopObjectStorageClient) Delete(ctx context.Context, key string) error {
 	return nil
 }
 
+func (noopObjectStorageClient) PresignGet(key string, ttl time.Duration) (string, error) {
+	return "", ErrPresignUnavailable
+}
+
 func newObjectStorageClient(cfg ObjectStorageConfig) objectStorageClient {
 	cfg = applyObjectStorageDefaults(cfg)
 	trimmedBucket := strings.TrimSpace(cfg.Bucket)
@@ -66,11 +108,30 @@ func newObjectStorageClient(cfg ObjectStorageConfig) objectStorageClient {
 	client := &s3ObjectStorageClient{
 		cfg:        sanitized,
 		endpoint:   baseURL,
-		httpClient: &http.Client{Timeout: sanitized.RequestTimeout},
+		httpClient: &http.Client{},
 	}
 	return client
 }
 
+// CheckObjectStorage sends a signed HEAD request for the configured bucket,
+// confirming the endpoint answers and the credentials can reach the bucket.
+func CheckObjectStorage(ctx context.Context, cfg ObjectStorageConfig) error {
+	client, ok := newObjectStorageClient(cfg).(*s3ObjectStorageClient)
+	if !ok {
+		return fmt.Errorf("object storage endpoint and bucket are required")
+	}
+	_, err := client.do(ctx, objectStorageRequest{
+		operation: "head_bucket",
+		method:    http.MethodHead,
+		target:    client.objectURL(""),
+		timeout:   client.cfg.requestTimeout(),
+	})
+	if err != nil {
+		return fmt.Errorf("head bucket %s: %w", client.cfg.Bucket, err)
+	}
+	return nil
+}
+
 type s3ObjectStorageClient struct {
 	cfg        ObjectStorageConfig
 	endpoint   *url.URL
@@ -81,52 +142,317 @@ func (c *s3ObjectStorageClient) Enabled() bool { return true }
 
 func (c *s3ObjectStorageClient) Upload(ctx context.Context, key, contentType string, body []byte) (objectReference, error) {
 	finalKey := c.applyPrefix(key)
-	target := c.objectURL(finalKey)
-	request, err := http.NewRequestWithContext(ctx, http.MethodPut, target.String(), bytes.NewReader(body))
+	if int64(len(body)) > c.cfg.MultipartThreshold {
+		start := time.Now()
+		if err := c.multipartUpload(ctx, finalKey, contentType, body); err != nil {
+			return objectReference{}, fmt.Errorf("upload object %s: %w", finalKey, err)
+		}
+		metrics.Default().ObserveObjectStorage("multipart_upload", time.Since(start))
+		return objectReference{Key: finalKey, URL: c.publicURL(finalKey)}, nil
+	}
+	start := time.Now()
+	_, err := c.do(ctx, objectStorageRequest{
+		operation:   "upload",
+		method:      http.MethodPut,
+		target:      c.objectURL(finalKey),
+		contentType: contentType,
+		body:        body,
+		timeout:     c.cfg.uploadTimeout(len(body)),
+		retry:       true,
+	})
+	if err != nil {
+		return objectReference{}, fmt.Errorf("upload object %s: %w", finalKey, err)
+	}
+	metrics.Default().ObserveObjectStorage("upload", time.Since(start))
+	return objectReference{Key: finalKey, URL: c.publicURL(finalKey)}, nil
+}
+
+func (c *s3ObjectStorageClient) Delete(ctx context.Context, key string) error {
+	finalKey := c.applyPrefix(key)
+	start := time.Now()
+	_, err := c.do(ctx, objectStorageRequest{
+		operation: "delete",
+		method:    http.MethodDelete,
+		target:    c.objectURL(finalKey),
+		timeout:   c.cfg.requestTimeout(),
+		retry:     true,
+	})
 	if err != nil {
-		return objectReference{}, fmt.Errorf("create upload request: %w", err)
+		return fmt.Errorf("delete object %s: %w", finalKey, err)
+	}
+	metrics.Default().ObserveObjectStorage("delete", time.Since(start))
+	return nil
+}
+
+// PresignGet signs a GET for key with SigV4 query parameters. The signature
+// covers only the host header, so the URL works from any client until ttl,
+// capped at seven days, has passed.
+func (c *s3ObjectStorageClient) PresignGet(key string, ttl time.Duration) (string, error) {
+	accessKey := strings.TrimSpace(c.cfg.AccessKey)
+	secretKey := strings.TrimSpace(c.cfg.SecretKey)
+	if accessKey == "" || secretKey == "" {
+		return "", ErrPresignUnavailable
 	}
-	if contentType != "" {
-		request.Header.Set("Content-Type", contentType)
+	if ttl < time.Second {
+		ttl = time.Second
 	}
-	hash := hashSHA256Hex(body)
-	if err := c.signRequest(request, hash); err != nil {
-		return objectReference{}, err
+	if ttl > maxObjectStoragePresignTTL {
+		ttl = maxObjectStoragePresignTTL
 	}
-	response, err := c.httpClient.Do(request)
+	region := strings.TrimSpace(c.cfg.Region)
+	if region == "" {
+		region = "us-east-1"
+	}
+	now := time.Now().UTC()
+	amzDate := now.Format("20060102T150405Z")
+	dateStamp := now.Format("20060102")
+	scope := strings.Join([]string{dateStamp, region, "s3", "aws4_request"}, "/")
+
+	target := c.objectURL(c.applyPrefix(key))
+	target.RawQuery = url.Values{
+		"X-Amz-Algorithm":     []string{"AWS4-HMAC-SHA256"},
+		"X-Amz-Credential":    []string{accessKey + "/" + scope},
+		"X-Amz-Date":          []string{amzDate},
+		"X-Amz-Expires":       []string{strconv.Itoa(int(ttl / time.Second))},
+		"X-Amz-SignedHeaders": []string{"host"},
+	}.Encode()
+	canonicalRequest := strings.Join([]string{
+		http.MethodGet,
+		canonicalURI(target),
+		canonicalQuery(target),
+		"host:" + target.Host + "\n",
+		"host",
+		"UNSIGNED-PAYLOAD",
+	}, "\n")
+	hash := sha256.Sum256([]byte(canonicalRequest))
+	stringToSign := strings.Join([]string{
+		"AWS4-HMAC-SHA256",
+		amzDate,
+		scope,
+		hex.EncodeToString(hash[:]),
+	}, "\n")
+	signature := hmacSHA256Hex(deriveSigningKey(secretKey, dateStamp, region), stringToSign)
+	target.RawQuery = canonicalQuery(target) + "&X-Amz-Signature=" + signature
+	return target.String(), nil
+}
+
+type initiateMultipartUploadResult struct {
+	UploadID string `xml:"UploadId"`
+}
+
+type completeMultipartUpload struct {
+	XMLName xml.Name                `xml:"CompleteMultipartUpload"`
+	Parts   []completeMultipartPart `xml:"Part"`
+}
+
+type completeMultipartPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+type objectStorageErrorResponse struct {
+	XMLName xml.Name `xml:"Error"`
+	Code    string   `xml:"Code"`
+	Message string   `xml:"Message"`
+}
+
+// multipartUpload splits body into parts of MultipartPartSize and uploads
+// them individually so a transient failure only retries the affected part.
+// Any failure after the upload has been initiated aborts it so incomplete
+// parts do not accumulate storage charges in the bucket.
+func (c *s3ObjectStorageClient) multipartUpload(ctx context.Context, finalKey, contentType string, body []byte) error {
+	initiate := c.objectURL(finalKey)
+	initiate.RawQuery = "uploads="
+	response, err := c.do(ctx, objectStorageRequest{
+		operation:   "multipart_initiate",
+		method:      http.MethodPost,
+		target:      initiate,
+		contentType: contentType,
+		timeout:     c.cfg.requestTimeout(),
+	})
 	if err != nil {
-		return objectReference{}, fmt.Errorf("upload object %s: %w", finalKey, err)
+		return fmt.Errorf("initiate multipart upload: %w", err)
 	}
-	defer func() {
-		_ = response.Body.Close()
-	}()
-	if response.StatusCode < 200 || response.StatusCode >= 300 {
-		return objectReference{}, fmt.Errorf("upload object %s: unexpected status %d", finalKey, response.StatusCode)
+	var initiated initiateMultipartUploadResult
+	if err := xml.Unmarshal(response.body, &initiated); err != nil {
+		return fmt.Errorf("decode multipart upload id: %w", err)
 	}
-	return objectReference{Key: finalKey, URL: c.publicURL(finalKey)}, nil
+	uploadID := strings.TrimSpace(initiated.UploadID)
+	if uploadID == "" {
+		return fmt.Errorf("initiate multipart upload: missing upload id")
+	}
+
+	if err := c.uploadParts(ctx, finalKey, uploadID, body); err != nil {
+		c.abortMultipartUpload(finalKey, uploadID)
+		return err
+	}
+	return nil
 }
 
-func (c *s3ObjectStorageClient) Delete(ctx context.Context, key string) error {
-	finalKey := c.applyPrefix(key)
+func (c *s3ObjectStorageClient) uploadParts(ctx context.Context, finalKey, uploadID string, body []byte) error {
+	partSize := c.cfg.MultipartPartSize
+	parts := make([]completeMultipartPart, 0, (int64(len(body))+partSize-1)/partSize)
+	for offset, number := int64(0), 1; offset < int64(len(body)); offset, number = offset+partSize, number+1 {
+		end := offset + partSize
+		if end > int64(len(body)) {
+			end = int64(len(body))
+		}
+		chunk := body[offset:end]
+		target := c.objectURL(finalKey)
+		target.RawQuery = url.Values{
+			"partNumber": []string{strconv.Itoa(number)},
+			"uploadId":   []string{uploadID},
+		}.Encode()
+		response, err := c.do(ctx, objectStorageRequest{
+			operation: "multipart_part",
+			method:    http.MethodPut,
+			target:    target,
+			body:      chunk,
+			timeout:   c.cfg.uploadTimeout(len(chunk)),
+			retry:     true,
+		})
+		if err != nil {
+			return fmt.Errorf("upload part %d: %w", number, err)
+		}
+		etag := strings.TrimSpace(response.header.Get("ETag"))
+		if etag == "" {
+			return fmt.Errorf("upload part %d: missing etag", number)
+		}
+		parts = append(parts, completeMultipartPart{PartNumber: number, ETag: etag})
+	}
+
+	payload, err := xml.Marshal(completeMultipartUpload{Parts: parts})
+	if err != nil {
+		return fmt.Errorf("encode multipart completion: %w", err)
+	}
 	target := c.objectURL(finalKey)
-	request, err := http.NewRequestWithContext(ctx, http.MethodDelete, target.String(), nil)
+	target.RawQuery = url.Values{"uploadId": []string{uploadID}}.Encode()
+	response, err := c.do(ctx, objectStorageRequest{
+		operation:   "multipart_complete",
+		method:      http.MethodPost,
+		target:      target,
+		contentType: "application/xml",
+		body:        payload,
+		timeout:     c.cfg.requestTimeout(),
+		retry:       true,
+	})
 	if err != nil {
-		return fmt.Errorf("create delete request: %w", err)
+		return fmt.Errorf("complete multipart upload: %w", err)
 	}
-	if err := c.signRequest(request, emptyPayloadHash); err != nil {
-		return err
+	// S3 may report a failed completion with a 200 status and an error
+	// document in the body.
+	var failure objectStorageErrorResponse
+	if xml.Unmarshal(response.body, &failure) == nil && failure.Code != "" {
+		return fmt.Errorf("complete multipart upload: %s: %s", failure.Code, failure.Message)
+	}
+	return nil
+}
+
+// abortMultipartUpload discards the parts uploaded so far. It deliberately
+// ignores the caller's context, which is typically already cancelled or
+// expired when cleanup runs.
+func (c *s3ObjectStorageClient) abortMultipartUpload(finalKey, uploadID string) {
+	target := c.objectURL(finalKey)
+	target.RawQuery = url.Values{"uploadId": []string{uploadID}}.Encode()
+	_, _ = c.do(context.Background(), objectStorageRequest{
+		operation: "multipart_abort",
+		method:    http.MethodDelete,
+		target:    target,
+		timeout:   c.cfg.requestTimeout(),
+		retry:     true,
+	})
+}
+
+type objectStorageRequest struct {
+	operation   string
+	method      string
+	target      *url.URL
+	contentType string
+	body        []byte
+	timeout     time.Duration
+	// retry marks the request as idempotent so transient failures can be
+	// retried without side effects.
+	retry bool
+}
+
+type objectStorageResponse struct {
+	header http.Header
+	body   []byte
+}
+
+// do sends a signed request, retrying idempotent requests with exponential
+// backoff when the object store is unreachable or returns a 5xx/429 status.
+func (c *s3ObjectStorageClient) do(ctx context.Context, req objectStorageRequest) (objectStorageResponse, error) {
+	attempts := 1
+	if req.retry {
+		attempts += c.cfg.MaxRetries
+	}
+	backoff := c.cfg.RetryBackoff
+	var lastErr error
+	for attempt := 1; attempt <= attempts; attempt++ {
+		response, retryable, err := c.attempt(ctx, req)
+		if err == nil {
+			return response, nil
+		}
+		lastErr = err
+		if !retryable || attempt == attempts || ctx.Err() != nil {
+			break
+		}
+		metrics.Default().ObserveObjectStorageRetry(req.operation)
+		timer := time.NewTimer(backoff)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return objectStorageResponse{}, ctx.Err()
+		case <-timer.C:
+		}
+		backoff *= 2
+		if backoff > maxObjectStorageRetryBackoff {
+			backoff = maxObjectStorageRetryBackoff
+		}
+	}
+	return objectStorageResponse{}, lastErr
+}
+
+func (c *s3ObjectStorageClient) attempt(ctx context.Context, req objectStorageRequest) (objectStorageResponse, bool, error) {
+	attemptCtx := ctx
+	if req.timeout > 0 {
+		var cancel context.CancelFunc
+		attemptCtx, cancel = context.WithTimeout(ctx, req.timeout)
+		defer cancel()
+	}
+	var reader io.Reader
+	if req.body != nil {
+		reader = bytes.NewReader(req.body)
+	}
+	request, err := http.NewRequestWithContext(attemptCtx, req.method, req.target.String(), reader)
+	if err != nil {
+		return objectStorageResponse{}, false, fmt.Errorf("create request: %w", err)
+	}
+	if req.contentType != "" {
+		request.Header.Set("Content-Type", req.contentType)
+	}
+	if err := c.signRequest(request, hashSHA256Hex(req.body)); err != nil {
+		return objectStorageResponse{}, false, err
 	}
 	response, err := c.httpClient.Do(request)
 	if err != nil {
-		return fmt.Errorf("delete object %s: %w", finalKey, err)
+		// A cancelled caller context is final; a timed out attempt or a
+		// network error may succeed on the next try.
+		return objectStorageResponse{}, ctx.Err() == nil, err
 	}
 	defer func() {
 		_ = response.Body.Close()
 	}()
-	if response.StatusCode >= 200 && response.StatusCode < 300 {
-		return nil
+	payload, err := io.ReadAll(response.Body)
+	if err != nil {
+		return objectStorageResponse{}, ctx.Err() == nil, fmt.Errorf("read response: %w", err)
+	}
+	if response.StatusCode < 200 || response.StatusCode >= 300 {
+		retryable := response.StatusCode >= 500 || response.StatusCode == http.StatusTooManyRequests
+		return objectStorageResponse{}, retryable, fmt.Errorf("unexpected status %d", response.StatusCode)
 	}
-	return fmt.Errorf("delete object %s: unexpected status %d", finalKey, response.StatusCode)
+	return objectStorageResponse{header: response.Header, body: payload}, false, nil
 }
 
 func (c *s3ObjectStorageClient) applyPrefix(key string) string {
@@ -3,23 +3,29 @@ package storage
 import (
 	"bytes"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
 	"path/filepath"
 	"runtime"
 	"strings"
 	"sync"
 	"testing"
+	"time"
+
+	"bitriver-live/internal/observability/metrics"
 )
 
 type fakeObjectStorage struct {
-	uploads []fakeUpload
-	deletes []string
-	prefix  string
-	baseURL string
+	uploads   []fakeUpload
+	deletes   []string
+	presigned []string
+	prefix    string
+	baseURL   string
 }
 
 type hangingDeleteObjectStorage struct{}
@@ -105,6 +111,8 @@ func (m *memoryS3Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	case http.MethodDelete:
 		delete(bucketObjects, key)
 		w.WriteHeader(http.StatusNoContent)
+	case http.MethodHead:
+		w.WriteHeader(http.StatusOK)
 	default:
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
 	}
@@ -197,6 +205,62 @@ func TestS3ObjectStorageClientUploadDelete(t *testing.T) {
 	}
 }
 
+func TestS3ObjectStorageClientPresignGet(t *testing.T) {
+	cfg := ObjectStorageConfig{
+		Endpoint:  "objects.example.com",
+		Region:    "eu-west-1",
+		AccessKey: "AKIAEXAMPLE",
+		SecretKey: "secretKeyExample",
+		Bucket:    "vod",
+		UseSSL:    true,
+		Prefix:    "vod/assets",
+	}
+	client, ok := newObjectStorageClient(cfg).(*s3ObjectStorageClient)
+	if !ok {
+		t.Fatal("expected s3ObjectStorageClient")
+	}
+
+	signed, err := client.PresignGet("recordings/rec-1/manifests/720p.json", 90*time.Second)
+	if err != nil {
+		t.Fatalf("PresignGet returned error: %v", err)
+	}
+	parsed, err := url.Parse(signed)
+	if err != nil {
+		t.Fatalf("parse signed url: %v", err)
+	}
+	if parsed.Host != "objects.example.com" || parsed.Path != "/vod/vod/assets/recordings/rec-1/manifests/720p.json" {
+		t.Fatalf("unexpected signed target %s", signed)
+	}
+	query := parsed.Query()
+	if query.Get("X-Amz-Algorithm") != "AWS4-HMAC-SHA256" || query.Get("X-Amz-SignedHeaders") != "host" {
+		t.Fatalf("expected SigV4 query parameters, got %v", query)
+	}
+	if query.Get("X-Amz-Expires") != "90" {
+		t.Fatalf("expected a 90 second expiry, got %q", query.Get("X-Amz-Expires"))
+	}
+	if !strings.HasPrefix(query.Get("X-Amz-Credential"), "AKIAEXAMPLE/") || !strings.HasSuffix(query.Get("X-Amz-Credential"), "/eu-west-1/s3/aws4_request") {
+		t.Fatalf("unexpected credential scope %q", query.Get("X-Amz-Credential"))
+	}
+	if len(query.Get("X-Amz-Signature")) != 64 {
+		t.Fatalf("expected a hex signature, got %q", query.Get("X-Amz-Signature"))
+	}
+
+	// Keys that already carry the prefix are not prefixed twice.
+	again, err := client.PresignGet("vod/assets/recordings/rec-1/manifests/720p.json", 30*24*time.Hour)
+	if err != nil {
+		t.Fatalf("PresignGet returned error: %v", err)
+	}
+	if !strings.Contains(again, "/vod/vod/assets/recordings/") || !strings.Contains(again, "X-Amz-Expires=604800") {
+		t.Fatalf("expected prefixed key with expiry capped at seven days, got %s", again)
+	}
+
+	cfg.SecretKey = ""
+	anonymous := newObjectStorageClient(cfg)
+	if _, err := anonymous.PresignGet("recordings/rec-1/manifests/720p.json", time.Minute); !errors.Is(err, ErrPresignUnavailable) {
+		t.Fatalf("expected ErrPresignUnavailable without credentials, got %v", err)
+	}
+}
+
 func TestStorageLoadsEmptyFile(t *testing.T) {
 	dir := t.TempDir()
 	path := filepath.Join(dir, "store.json")
@@ -208,7 +272,11 @@ func TestStorageLoadsEmptyFile(t *testing.T) {
 	if err != nil {
 		t.Fatalf("NewStorage: %v", err)
 	}
-	if users := store.ListUsers(); len(users) != 0 {
+	users, err := store.ListUsers()
+	if err != nil {
+		t.Fatalf("ListUsers: %v", err)
+	}
+	if len(users) != 0 {
 		t.Fatalf("expected no users, got %d", len(users))
 	}
 
@@ -304,6 +372,11 @@ func (f *fakeObjectStorage) Delete(ctx context.Context, key string) error {
 	return nil
 }
 
+func (f *fakeObjectStorage) PresignGet(key string, ttl time.Duration) (string, error) {
+	f.presigned = append(f.presigned, key)
+	return fmt.Sprintf("https://signed.example.com/%s?expires=%d", strings.TrimLeft(key, "/"), int(ttl/time.Second)), nil
+}
+
 func (h *hangingDeleteObjectStorage) Enabled() bool { return true }
 
 func (h *hangingDeleteObjectStorage) Upload(ctx context.Context, key, contentType string, body []byte) (objectReference, error) {
@@ -314,3 +387,281 @@ func (h *hangingDeleteObjectStorage) Delete(ctx context.Context, key string) err
 	<-ctx.Done()
 	return ctx.Err()
 }
+
+func (h *hangingDeleteObjectStorage) PresignGet(key string, ttl time.Duration) (string, error) {
+	return "", ErrPresignUnavailable
+}
+
+type flakyS3Server struct {
+	mu         sync.Mutex
+	objects    map[string][]byte
+	uploads    map[string]map[int][]byte
+	requests   []string
+	failures   map[string]int
+	failAlways map[string]bool
+	nextID     int
+}
+
+func newFlakyS3Server() *flakyS3Server {
+	return &flakyS3Server{
+		objects:    make(map[string][]byte),
+		uploads:    make(map[string]map[int][]byte),
+		failures:   make(map[string]int),
+		failAlways: make(map[string]bool),
+	}
+}
+
+// flakyRequestKey identifies a request by method and the multipart query
+// parameters so tests can target a specific step of an upload.
+func flakyRequestKey(r *http.Request) string {
+	query := r.URL.Query()
+	switch {
+	case query.Has("uploads"):
+		return r.Method + " initiate"
+	case query.Has("partNumber"):
+		return r.Method + " part " + query.Get("partNumber")
+	case query.Has("uploadId") && r.Method == http.MethodPost:
+		return r.Method + " complete"
+	case query.Has("uploadId") && r.Method == http.MethodDelete:
+		return r.Method + " abort"
+	default:
+		return r.Method
+	}
+}
+
+func (f *flakyS3Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	body, err := io.ReadAll(r.Body)
+	if err != nil {
+		http.Error(w, "read body", http.StatusInternalServerError)
+		return
+	}
+	_, key, err := parseS3Path(r.URL.Path)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	requestKey := flakyRequestKey(r)
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.requests = append(f.requests, requestKey)
+	if f.failAlways[requestKey] {
+		http.Error(w, "injected failure", http.StatusInternalServerError)
+		return
+	}
+	if f.failures[requestKey] > 0 {
+		f.failures[requestKey]--
+		http.Error(w, "injected failure", http.StatusInternalServerError)
+		return
+	}
+	query := r.URL.Query()
+	switch {
+	case strings.HasSuffix(requestKey, "initiate"):
+		f.nextID++
+		uploadID := fmt.Sprintf("upload-%d", f.nextID)
+		f.uploads[uploadID] = make(map[int][]byte)
+		fmt.Fprintf(w, "<InitiateMultipartUploadResult><Key>%s</Key><UploadId>%s</UploadId></InitiateMultipartUploadResult>", key, uploadID)
+	case strings.HasPrefix(requestKey, "PUT part"):
+		parts, ok := f.uploads[query.Get("uploadId")]
+		if !ok {
+			http.Error(w, "no such upload", http.StatusNotFound)
+			return
+		}
+		var number int
+		fmt.Sscanf(query.Get("partNumber"), "%d", &number)
+		parts[number] = append([]byte(nil), body...)
+		w.Header().Set("ETag", fmt.Sprintf("\"etag-%d\"", number))
+	case strings.HasSuffix(requestKey, "complete"):
+		parts, ok := f.uploads[query.Get("uploadId")]
+		if !ok {
+			http.Error(w, "no such upload", http.StatusNotFound)
+			return
+		}
+		var assembled []byte
+		for number := 1; number <= len(parts); number++ {
+			assembled = append(assembled, parts[number]...)
+		}
+		f.objects[key] = assembled
+		delete(f.uploads, query.Get("uploadId"))
+		fmt.Fprint(w, "<CompleteMultipartUploadResult></CompleteMultipartUploadResult>")
+	case strings.HasSuffix(requestKey, "abort"):
+		delete(f.uploads, query.Get("uploadId"))
+		w.WriteHeader(http.StatusNoContent)
+	case r.Method == http.MethodPut:
+		f.objects[key] = append([]byte(nil), body...)
+	case r.Method == http.MethodDelete:
+		delete(f.objects, key)
+		w.WriteHeader(http.StatusNoContent)
+	default:
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+	}
+}
+
+func (f *flakyS3Server) snapshot() ([]string, map[string][]byte, int) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	requests := append([]string(nil), f.requests...)
+	objects := make(map[string][]byte, len(f.objects))
+	for key, value := range f.objects {
+		objects[key] = value
+	}
+	return requests, objects, len(f.uploads)
+}
+
+func newFlakyS3Client(t *testing.T, server *flakyS3Server, cfg ObjectStorageConfig) *s3ObjectStorageClient {
+	t.Helper()
+	ts := httptest.NewServer(server)
+	t.Cleanup(ts.Close)
+	cfg.Endpoint = strings.TrimPrefix(ts.URL, "http://")
+	cfg.Bucket = "vod"
+	cfg.AccessKey = "AKIAEXAMPLE"
+	cfg.SecretKey = "secretKeyExample"
+	if cfg.RetryBackoff == 0 {
+		cfg.RetryBackoff = time.Millisecond
+	}
+	client, ok := newObjectStorageClient(cfg).(*s3ObjectStorageClient)
+	if !ok {
+		t.Fatal("expected s3ObjectStorageClient")
+	}
+	return client
+}
+
+func TestCheckObjectStorage(t *testing.T) {
+	server := newMemoryS3Server()
+	server.addBucket("vod")
+	ts := httptest.NewServer(server)
+	defer ts.Close()
+
+	cfg := ObjectStorageConfig{
+		Endpoint:  strings.TrimPrefix(ts.URL, "http://"),
+		Region:    "us-east-1",
+		AccessKey: "AKIAEXAMPLE",
+		SecretKey: "secretKeyExample",
+		Bucket:    "vod",
+	}
+	if err := CheckObjectStorage(context.Background(), cfg); err != nil {
+		t.Fatalf("CheckObjectStorage returned error: %v", err)
+	}
+	if req := server.lastRequest(); req.Method != http.MethodHead || !strings.HasPrefix(req.Authorization, "AWS4-HMAC-SHA256") {
+		t.Fatalf("expected a signed HEAD request, got %+v", req)
+	}
+
+	cfg.Bucket = "missing"
+	if err := CheckObjectStorage(context.Background(), cfg); err == nil || !strings.Contains(err.Error(), "404") {
+		t.Fatalf("expected a missing bucket to fail with its status, got %v", err)
+	}
+	if err := CheckObjectStorage(context.Background(), ObjectStorageConfig{Bucket: "vod"}); err == nil {
+		t.Fatal("expected an error without an endpoint")
+	}
+}
+
+func TestS3ObjectStorageClientRetriesTransientFailures(t *testing.T) {
+	server := newFlakyS3Server()
+	server.failures[http.MethodPut] = 2
+	server.failures[http.MethodDelete] = 1
+	client := newFlakyS3Client(t, server, ObjectStorageConfig{MaxRetries: 3})
+
+	_, retriesBefore := metrics.Default().ObjectStorageCounts()
+	ref, err := client.Upload(context.Background(), "manifests/720p.json", "application/json", []byte(`{"name":"720p"}`))
+	if err != nil {
+		t.Fatalf("Upload returned error: %v", err)
+	}
+	if err := client.Delete(context.Background(), ref.Key); err != nil {
+		t.Fatalf("Delete returned error: %v", err)
+	}
+
+	requests, objects, _ := server.snapshot()
+	expected := []string{http.MethodPut, http.MethodPut, http.MethodPut, http.MethodDelete, http.MethodDelete}
+	if strings.Join(requests, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected requests %v, got %v", expected, requests)
+	}
+	if len(objects) != 0 {
+		t.Fatalf("expected object to be deleted, got %v", objects)
+	}
+	_, retriesAfter := metrics.Default().ObjectStorageCounts()
+	if got := retriesAfter["upload"] - retriesBefore["upload"]; got != 2 {
+		t.Fatalf("expected 2 upload retries recorded, got %d", got)
+	}
+	if got := retriesAfter["delete"] - retriesBefore["delete"]; got != 1 {
+		t.Fatalf("expected 1 delete retry recorded, got %d", got)
+	}
+}
+
+func TestS3ObjectStorageClientGivesUpAfterMaxRetries(t *testing.T) {
+	server := newFlakyS3Server()
+	server.failAlways[http.MethodPut] = true
+	client := newFlakyS3Client(t, server, ObjectStorageConfig{MaxRetries: 2})
+
+	if _, err := client.Upload(context.Background(), "thumb.json", "application/json", []byte("{}")); err == nil {
+		t.Fatal("expected upload to fail once retries are exhausted")
+	}
+	requests, _, _ := server.snapshot()
+	if len(requests) != 3 {
+		t.Fatalf("expected initial attempt plus 2 retries, got %v", requests)
+	}
+}
+
+func TestS3ObjectStorageClientMultipartThreshold(t *testing.T) {
+	server := newFlakyS3Server()
+	server.failures["PUT part 2"] = 1
+	client := newFlakyS3Client(t, server, ObjectStorageConfig{MultipartThreshold: 16, MultipartPartSize: 8})
+
+	small := []byte("0123456789")
+	if _, err := client.Upload(context.Background(), "small.bin", "application/octet-stream", small); err != nil {
+		t.Fatalf("Upload small returned error: %v", err)
+	}
+	requests, _, _ := server.snapshot()
+	if len(requests) != 1 || requests[0] != http.MethodPut {
+		t.Fatalf("expected a single PUT below the threshold, got %v", requests)
+	}
+
+	large := []byte("abcdefghijklmnopqrstuvwxyz")
+	ref, err := client.Upload(context.Background(), "large.bin", "application/octet-stream", large)
+	if err != nil {
+		t.Fatalf("Upload large returned error: %v", err)
+	}
+	requests, objects, pending := server.snapshot()
+	expected := []string{
+		http.MethodPut,
+		"POST initiate",
+		"PUT part 1",
+		"PUT part 2",
+		"PUT part 2",
+		"PUT part 3",
+		"PUT part 4",
+		"POST complete",
+	}
+	if strings.Join(requests, ",") != strings.Join(expected, ",") {
+		t.Fatalf("expected requests %v, got %v", expected, requests)
+	}
+	if !bytes.Equal(objects[ref.Key], large) {
+		t.Fatalf("expected assembled object %q, got %q", large, objects[ref.Key])
+	}
+	if pending != 0 {
+		t.Fatalf("expected no pending multipart uploads, got %d", pending)
+	}
+}
+
+func TestS3ObjectStorageClientAbortsFailedMultipart(t *testing.T) {
+	server := newFlakyS3Server()
+	server.failAlways["PUT part 2"] = true
+	client := newFlakyS3Client(t, server, ObjectStorageConfig{MultipartThreshold: 16, MultipartPartSize: 8, MaxRetries: 1})
+
+	if _, err := client.Upload(context.Background(), "large.bin", "application/octet-stream", []byte("abcdefghijklmnopqrstuvwxyz")); err == nil {
+		t.Fatal("expected multipart upload to fail")
+	}
+	requests, objects, pending := server.snapshot()
+	if last := requests[len(requests)-1]; last != "DELETE abort" {
+		t.Fatalf("expected failed upload to be aborted, got requests %v", requests)
+	}
+	for _, req := range requests {
+		if req == "PUT part 3" || req == "POST complete" {
+			t.Fatalf("expected upload to stop after failed part, got requests %v", requests)
+		}
+	}
+	if pending != 0 {
+		t.Fatalf("expected abort to discard uploaded parts, %d uploads pending", pending)
+	}
+	if len(objects) != 0 {
+		t.Fatalf("expected no object to be stored, got %v", objects)
+	}
+}
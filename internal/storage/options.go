@@ -93,6 +93,25 @@ func WithIngestTimeout(timeout time.Duration) Option {
 	)
 }
 
+// WithStartingTimeout changes how long a channel may sit in the starting
+// state. Until it elapses, StartStream treats the start as in progress and
+// refuses a second one; afterwards the start counts as stuck and RecoverStream
+// may reset the channel.
+func WithStartingTimeout(timeout time.Duration) Option {
+	return composeOption(
+		func(s *Storage) {
+			if timeout > 0 {
+				s.startingTimeout = timeout
+			}
+		},
+		func(cfg *PostgresConfig) {
+			if timeout > 0 {
+				cfg.StartingTimeout = timeout
+			}
+		},
+	)
+}
+
 // WithRecordingRetention customises how long published and unpublished
 // recordings are retained before cleanup.
 func WithRecordingRetention(policy RecordingRetentionPolicy) Option {
@@ -116,6 +135,40 @@ func WithRecordingRetention(policy RecordingRetentionPolicy) Option {
 	)
 }
 
+// WithChannelStorageQuota sets the platform default for how many bytes of
+// recordings and uploads each channel may keep. Zero, the default, leaves
+// channels unlimited unless an admin sets an override.
+func WithChannelStorageQuota(bytes int64) Option {
+	if bytes < 0 {
+		bytes = 0
+	}
+	return composeOption(
+		func(s *Storage) {
+			s.storageQuota = bytes
+		},
+		func(cfg *PostgresConfig) {
+			cfg.ChannelStorageQuota = bytes
+		},
+	)
+}
+
+// WithMaxConcurrentStreams sets the platform default for how many of a
+// user's channels may stream at once. Zero, the default, leaves users
+// unlimited unless an admin sets an override.
+func WithMaxConcurrentStreams(limit int) Option {
+	if limit < 0 {
+		limit = 0
+	}
+	return composeOption(
+		func(s *Storage) {
+			s.streamLimit = limit
+		},
+		func(cfg *PostgresConfig) {
+			cfg.MaxConcurrentStreams = limit
+		},
+	)
+}
+
 // WithRetentionClock overrides the clock used when evaluating recording
 // retention windows. Primarily intended for tests that need deterministic
 // retention behaviour.
@@ -134,6 +187,24 @@ func WithRetentionClock(clock func() time.Time) Option {
 	)
 }
 
+// WithGrantClock overrides the clock used to stamp channel moderator and
+// editor grants and to decide whether time-limited grants have expired.
+// Primarily intended for tests that need deterministic expiry.
+func WithGrantClock(clock func() time.Time) Option {
+	return composeOption(
+		func(s *Storage) {
+			if clock != nil {
+				s.grantNow = clock
+			}
+		},
+		func(cfg *PostgresConfig) {
+			if clock != nil {
+				cfg.GrantClock = clock
+			}
+		},
+	)
+}
+
 // WithObjectStorage overrides the object storage configuration used to archive
 // or retrieve recording assets.
 func WithObjectStorage(cfg ObjectStorageConfig) Option {
@@ -175,6 +246,28 @@ func WithPostgresAcquireTimeout(timeout time.Duration) Option {
 	})
 }
 
+// WithPostgresStatementTimeout bounds every statement the repository runs
+// inside a transaction; the default is 30 seconds. With an acquire timeout
+// configured, statements are also cut off when that deadline passes.
+func WithPostgresStatementTimeout(timeout time.Duration) Option {
+	return postgresOnlyOption(func(cfg *PostgresConfig) {
+		if timeout > 0 {
+			cfg.StatementTimeout = timeout
+		}
+	})
+}
+
+// WithPostgresTxRetries sets how many times a transaction that is safe to
+// rerun is retried after a serialization failure or deadlock. Zero disables
+// retries; the default is 3.
+func WithPostgresTxRetries(retries int) Option {
+	return postgresOnlyOption(func(cfg *PostgresConfig) {
+		if retries >= 0 {
+			cfg.TxMaxRetries = retries
+		}
+	})
+}
+
 // WithPostgresPoolDurations adjusts how long connections live, how long they
 // may remain idle, and how frequently health checks run against the pool.
 func WithPostgresPoolDurations(maxLifetime, maxIdle, healthInterval time.Duration) Option {
@@ -201,3 +294,50 @@ func WithPostgresApplicationName(name string) Option {
 		}
 	})
 }
+
+// WithPostgresReplicas adds read replicas of the primary database. Lag-tolerant
+// reads such as directory listings and chat history are served from them,
+// falling back to the primary while none is reachable.
+func WithPostgresReplicas(dsns ...string) Option {
+	return postgresOnlyOption(func(cfg *PostgresConfig) {
+		for _, dsn := range dsns {
+			if trimmed := strings.TrimSpace(dsn); trimmed != "" {
+				cfg.ReplicaDSNs = append(cfg.ReplicaDSNs, trimmed)
+			}
+		}
+	})
+}
+
+// WithSecretKey sets the server secret key that seals secrets stored by the
+// repository, such as restream stream keys. The key must be SecretKeyLength
+// bytes; see ParseSecretKey. Without a key those secrets cannot be saved.
+func WithSecretKey(key []byte) Option {
+	stored := append([]byte(nil), key...)
+	return composeOption(
+		func(s *Storage) {
+			s.secretKey = stored
+		},
+		func(cfg *PostgresConfig) {
+			cfg.SecretKey = stored
+		},
+	)
+}
+
+// WithEncryptionKeys sets the named keys that encrypt sensitive fields such
+// as donation addresses and OAuth emails; see ParseEncryptionKeys. The first
+// key seals new values and the rest open values sealed before a rotation.
+// Values sealed with the server secret key keep opening with it.
+func WithEncryptionKeys(keys []EncryptionKey) Option {
+	stored := make([]EncryptionKey, 0, len(keys))
+	for _, key := range keys {
+		stored = append(stored, EncryptionKey{ID: key.ID, Key: append([]byte(nil), key.Key...)})
+	}
+	return composeOption(
+		func(s *Storage) {
+			s.encryptionKeys = stored
+		},
+		func(cfg *PostgresConfig) {
+			cfg.EncryptionKeys = stored
+		},
+	)
+}
@@ -0,0 +1,131 @@
+package storage
+
+import (
+	"context"
+	"time"
+
+	"bitriver-live/internal/models"
+)
+
+// PlatformStats is a rollup of platform-wide activity, stored once per UTC
+// day. Rolling up again on the same day replaces that day's row, so the
+// latest row always reflects the most recent run. Rollups are derived data
+// and are not carried across snapshot imports.
+type PlatformStats struct {
+	// Day is the UTC day the rollup covers, formatted as 2006-01-02.
+	Day             string `json:"day"`
+	TotalChannels   int    `json:"totalChannels"`
+	LiveChannels    int    `json:"liveChannels"`
+	RegisteredUsers int    `json:"registeredUsers"`
+	NewUsersToday   int    `json:"newUsersToday"`
+	// SessionsStartedToday counts stream sessions started since midnight UTC.
+	SessionsStartedToday int `json:"sessionsStartedToday"`
+	// StreamedSecondsThisMonth sums the time every stream session spent live
+	// since the start of the UTC month, counting running sessions up to the
+	// rollup.
+	StreamedSecondsThisMonth int64 `json:"streamedSecondsThisMonth"`
+	// ChatMessagesToday counts chat messages sent since midnight UTC.
+	ChatMessagesToday int       `json:"chatMessagesToday"`
+	ComputedAt        time.Time `json:"computedAt"`
+}
+
+// platformStatsWindow returns now in UTC along with the start of its UTC day
+// and month.
+func platformStatsWindow(now time.Time) (utcNow, dayStart, monthStart time.Time) {
+	now = now.UTC()
+	dayStart = time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	monthStart = time.Date(now.Year(), now.Month(), 1, 0, 0, 0, 0, time.UTC)
+	return now, dayStart, monthStart
+}
+
+// streamedSeconds is how long session was live between monthStart and now.
+func streamedSeconds(session models.StreamSession, monthStart, now time.Time) int64 {
+	start := session.StartedAt
+	if start.Before(monthStart) {
+		start = monthStart
+	}
+	end := now
+	if session.EndedAt != nil && session.EndedAt.Before(now) {
+		end = *session.EndedAt
+	}
+	if !end.After(start) {
+		return 0
+	}
+	return int64(end.Sub(start) / time.Second)
+}
+
+// RollupPlatformStats computes the platform aggregates as of now and stores
+// them as the rollup for now's UTC day.
+func (s *Storage) RollupPlatformStats(ctx context.Context, now time.Time) (PlatformStats, error) {
+	if err := ctx.Err(); err != nil {
+		return PlatformStats{}, err
+	}
+	now, dayStart, monthStart := platformStatsWindow(now)
+	stats := PlatformStats{Day: dayStart.Format("2006-01-02"), ComputedAt: now}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	stats.TotalChannels = len(s.data.Channels)
+	for _, channel := range s.data.Channels {
+		if channel.LiveState == "live" {
+			stats.LiveChannels++
+		}
+	}
+	for _, user := range s.data.Users {
+		if user.DeactivatedAt != nil || user.CreatedAt.After(now) {
+			continue
+		}
+		stats.RegisteredUsers++
+		if !user.CreatedAt.Before(dayStart) {
+			stats.NewUsersToday++
+		}
+	}
+	for _, session := range s.data.StreamSessions {
+		if session.StartedAt.After(now) {
+			continue
+		}
+		if !session.StartedAt.Before(dayStart) {
+			stats.SessionsStartedToday++
+		}
+		stats.StreamedSecondsThisMonth += streamedSeconds(session, monthStart, now)
+	}
+	for _, message := range s.data.ChatMessages {
+		if !message.CreatedAt.Before(dayStart) && !message.CreatedAt.After(now) {
+			stats.ChatMessagesToday++
+		}
+	}
+
+	if s.data.PlatformStats == nil {
+		s.data.PlatformStats = make(map[string]PlatformStats)
+	}
+	previous, hadPrevious := s.data.PlatformStats[stats.Day]
+	s.data.PlatformStats[stats.Day] = stats
+	if err := s.persist(); err != nil {
+		if hadPrevious {
+			s.data.PlatformStats[stats.Day] = previous
+		} else {
+			delete(s.data.PlatformStats, stats.Day)
+		}
+		return PlatformStats{}, err
+	}
+	return stats, nil
+}
+
+// LatestPlatformStats returns the most recently computed rollup, or false
+// when none has run yet.
+func (s *Storage) LatestPlatformStats() (PlatformStats, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	var (
+		latest PlatformStats
+		found  bool
+	)
+	for _, stats := range s.data.PlatformStats {
+		if !found || stats.ComputedAt.After(latest.ComputedAt) {
+			latest = stats
+			found = true
+		}
+	}
+	return latest, found
+}
@@ -0,0 +1,92 @@
+package storage
+
+import (
+	"fmt"
+	"time"
+
+	"bitriver-live/internal/models"
+)
+
+// PlaybackPreferencesUpdate changes some of a user's playback preferences.
+// Nil fields keep their current value.
+type PlaybackPreferencesUpdate struct {
+	DefaultQuality   *string
+	PreferLowLatency *bool
+	MutedAutoplay    *bool
+}
+
+// Apply validates the update and applies it to prefs. It returns
+// ErrInvalidPlaybackQuality for an unknown default quality and leaves prefs
+// untouched on error.
+func (u PlaybackPreferencesUpdate) Apply(prefs *models.PlaybackPreferences) error {
+	if u.DefaultQuality != nil {
+		quality, ok := models.NormalizePlaybackQuality(*u.DefaultQuality)
+		if !ok {
+			return fmt.Errorf("%w: %q", ErrInvalidPlaybackQuality, *u.DefaultQuality)
+		}
+		prefs.DefaultQuality = quality
+	}
+	if u.PreferLowLatency != nil {
+		prefs.PreferLowLatency = *u.PreferLowLatency
+	}
+	if u.MutedAutoplay != nil {
+		prefs.MutedAutoplay = *u.MutedAutoplay
+	}
+	return nil
+}
+
+func clonePlaybackPreferences(prefs models.PlaybackPreferences) models.PlaybackPreferences {
+	if prefs.UpdatedAt != nil {
+		updated := *prefs.UpdatedAt
+		prefs.UpdatedAt = &updated
+	}
+	return prefs
+}
+
+// GetPlaybackPreferences returns the user's playback preferences, falling
+// back to the defaults when they never changed them.
+func (s *Storage) GetPlaybackPreferences(userID string) (models.PlaybackPreferences, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if _, ok := s.data.Users[userID]; !ok {
+		return models.PlaybackPreferences{}, notFound("user", userID)
+	}
+	if prefs, ok := s.data.PlaybackPreferences[userID]; ok {
+		return clonePlaybackPreferences(prefs), nil
+	}
+	return models.DefaultPlaybackPreferences(userID), nil
+}
+
+// UpdatePlaybackPreferences applies update on top of the user's current
+// preferences and stores the result. It returns ErrInvalidPlaybackQuality
+// for an unknown default quality.
+func (s *Storage) UpdatePlaybackPreferences(userID string, update PlaybackPreferencesUpdate) (models.PlaybackPreferences, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, ok := s.data.Users[userID]; !ok {
+		return models.PlaybackPreferences{}, notFound("user", userID)
+	}
+	prefs, ok := s.data.PlaybackPreferences[userID]
+	if !ok {
+		prefs = models.DefaultPlaybackPreferences(userID)
+	}
+	if err := update.Apply(&prefs); err != nil {
+		return models.PlaybackPreferences{}, err
+	}
+	now := time.Now().UTC()
+	prefs.UpdatedAt = &now
+
+	updatedData := cloneDataset(s.data)
+	if updatedData.PlaybackPreferences == nil {
+		updatedData.PlaybackPreferences = make(map[string]models.PlaybackPreferences)
+	}
+	updatedData.PlaybackPreferences[userID] = prefs
+
+	if err := s.persistDataset(updatedData); err != nil {
+		return models.PlaybackPreferences{}, err
+	}
+	s.data = updatedData
+	return clonePlaybackPreferences(prefs), nil
+}
@@ -8,6 +8,9 @@ import (
 	"fmt"
 	"testing"
 	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
 )
 
 func TestPostgresRepositoryAcquireTimeout(t *testing.T) {
@@ -245,3 +248,51 @@ func TestPostgresRepositoryAcquireTimeoutCreateUpload(t *testing.T) {
 
 	conn.Release()
 }
+
+func TestPostgresRepositoryStatementTimeout(t *testing.T) {
+	repo, cleanup, err := postgresRepositoryFactory(t,
+		WithPostgresStatementTimeout(100*time.Millisecond),
+	)
+	if err != nil {
+		t.Fatalf("failed to open postgres repository: %v", err)
+	}
+	if cleanup != nil {
+		defer cleanup()
+	}
+
+	pgRepo, ok := repo.(*postgresRepository)
+	if !ok {
+		t.Fatalf("expected postgres repository instance")
+	}
+
+	start := time.Now()
+	err = pgRepo.withTx(txSpec{Name: "statement timeout test", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		_, err := tx.Exec(ctx, "SELECT pg_sleep(2)")
+		return err
+	})
+	if !errors.Is(err, ErrStatementTimeout) {
+		t.Fatalf("expected statement timeout; got %v", err)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected postgres to cancel the statement; got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Fatalf("statement ran for %s despite the timeout", elapsed)
+	}
+
+	// SET LOCAL ends with the transaction, so the pooled connection keeps the
+	// server default afterwards.
+	err = pgRepo.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		var setting string
+		if err := conn.QueryRow(ctx, "SHOW statement_timeout").Scan(&setting); err != nil {
+			return err
+		}
+		if setting == "100ms" {
+			return fmt.Errorf("statement_timeout leaked onto the connection")
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+}
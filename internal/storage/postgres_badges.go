@@ -0,0 +1,292 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"bitriver-live/internal/models"
+)
+
+const (
+	badgeDefinitionColumns = "slug, label, icon_url, created_at, updated_at"
+	badgeGrantColumns      = "user_id, badge_slug, granted_by, granted_at"
+)
+
+func scanBadgeDefinition(row pgx.Row) (models.BadgeDefinition, error) {
+	var definition models.BadgeDefinition
+	if err := row.Scan(&definition.Slug, &definition.Label, &definition.IconURL, &definition.CreatedAt, &definition.UpdatedAt); err != nil {
+		return models.BadgeDefinition{}, err
+	}
+	definition.CreatedAt = definition.CreatedAt.UTC()
+	definition.UpdatedAt = definition.UpdatedAt.UTC()
+	return definition, nil
+}
+
+func scanBadgeGrant(row pgx.Row) (models.BadgeGrant, error) {
+	var grant models.BadgeGrant
+	if err := row.Scan(&grant.UserID, &grant.Slug, &grant.GrantedBy, &grant.GrantedAt); err != nil {
+		return models.BadgeGrant{}, err
+	}
+	grant.GrantedAt = grant.GrantedAt.UTC()
+	return grant, nil
+}
+
+// queryBadgeGrants runs query, which must select badgeGrantColumns, and
+// collects the grants.
+func queryBadgeGrants(ctx context.Context, q rowsQuerier, query string, args ...any) ([]models.BadgeGrant, error) {
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list badge grants: %w", err)
+	}
+	defer rows.Close()
+	grants := make([]models.BadgeGrant, 0)
+	for rows.Next() {
+		grant, err := scanBadgeGrant(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan badge grant: %w", err)
+		}
+		grants = append(grants, grant)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate badge grants: %w", err)
+	}
+	return grants, nil
+}
+
+// globalBadgeSlugs returns the slugs of each user's global badges, oldest
+// grant first, for ChatAuthors.
+func globalBadgeSlugs(ctx context.Context, q rowsQuerier, userIDs []string) (map[string][]string, error) {
+	grants, err := queryBadgeGrants(ctx, q, "SELECT "+badgeGrantColumns+" FROM user_badges WHERE user_id = ANY($1) ORDER BY granted_at, badge_slug", userIDs)
+	if err != nil {
+		return nil, err
+	}
+	slugs := make(map[string][]string)
+	for _, grant := range grants {
+		slugs[grant.UserID] = append(slugs[grant.UserID], grant.Slug)
+	}
+	return slugs, nil
+}
+
+// ListBadgeDefinitions returns every badge definition in creation order.
+func (r *postgresRepository) ListBadgeDefinitions() ([]models.BadgeDefinition, error) {
+	if r == nil || r.pool == nil {
+		return nil, ErrPostgresUnavailable
+	}
+	definitions := make([]models.BadgeDefinition, 0)
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		rows, err := conn.Query(ctx, "SELECT "+badgeDefinitionColumns+" FROM badge_definitions ORDER BY created_at, slug")
+		if err != nil {
+			return fmt.Errorf("list badge definitions: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			definition, err := scanBadgeDefinition(rows)
+			if err != nil {
+				return fmt.Errorf("scan badge definition: %w", err)
+			}
+			definitions = append(definitions, definition)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return definitions, nil
+}
+
+// CreateBadgeDefinition adds a global badge. It returns ErrBadgeExists when
+// the slug is taken.
+func (r *postgresRepository) CreateBadgeDefinition(params BadgeDefinitionParams) (models.BadgeDefinition, error) {
+	if r == nil || r.pool == nil {
+		return models.BadgeDefinition{}, ErrPostgresUnavailable
+	}
+	definition, err := newBadgeDefinition(params, time.Now().UTC())
+	if err != nil {
+		return models.BadgeDefinition{}, err
+	}
+	err = r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		tag, err := conn.Exec(ctx, "INSERT INTO badge_definitions ("+badgeDefinitionColumns+") VALUES ($1, $2, $3, $4, $5) ON CONFLICT (slug) DO NOTHING",
+			definition.Slug,
+			definition.Label,
+			definition.IconURL,
+			definition.CreatedAt,
+			definition.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("insert badge definition: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrBadgeExists
+		}
+		return nil
+	})
+	if err != nil {
+		return models.BadgeDefinition{}, err
+	}
+	return definition, nil
+}
+
+// UpdateBadgeDefinition changes a badge's label or icon.
+func (r *postgresRepository) UpdateBadgeDefinition(slug string, update BadgeDefinitionUpdate) (models.BadgeDefinition, error) {
+	if r == nil || r.pool == nil {
+		return models.BadgeDefinition{}, ErrPostgresUnavailable
+	}
+	var definition models.BadgeDefinition
+	err := r.withTx(txSpec{Name: "badge definition", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		current, err := scanBadgeDefinition(tx.QueryRow(ctx, "SELECT "+badgeDefinitionColumns+" FROM badge_definitions WHERE slug = $1 FOR UPDATE", slug))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrBadgeNotFound
+			}
+			return fmt.Errorf("load badge definition %s: %w", slug, err)
+		}
+		updated, err := applyBadgeDefinitionUpdate(current, update, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, "UPDATE badge_definitions SET label = $1, icon_url = $2, updated_at = $3 WHERE slug = $4",
+			updated.Label,
+			updated.IconURL,
+			updated.UpdatedAt,
+			updated.Slug,
+		); err != nil {
+			return fmt.Errorf("update badge definition %s: %w", slug, err)
+		}
+		definition = updated
+		return nil
+	})
+	if err != nil {
+		return models.BadgeDefinition{}, err
+	}
+	return definition, nil
+}
+
+// DeleteBadgeDefinition removes a badge. It returns ErrBadgeInUse while any
+// user still holds it.
+func (r *postgresRepository) DeleteBadgeDefinition(slug string) error {
+	if r == nil || r.pool == nil {
+		return ErrPostgresUnavailable
+	}
+	return r.withTx(txSpec{Name: "badge definition", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		var locked string
+		if err := tx.QueryRow(ctx, "SELECT slug FROM badge_definitions WHERE slug = $1 FOR UPDATE", slug).Scan(&locked); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrBadgeNotFound
+			}
+			return fmt.Errorf("lock badge definition %s: %w", slug, err)
+		}
+		var granted bool
+		if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM user_badges WHERE badge_slug = $1)", slug).Scan(&granted); err != nil {
+			return fmt.Errorf("check badge grants: %w", err)
+		}
+		if granted {
+			return ErrBadgeInUse
+		}
+		if _, err := tx.Exec(ctx, "DELETE FROM badge_definitions WHERE slug = $1", slug); err != nil {
+			return fmt.Errorf("delete badge definition %s: %w", slug, err)
+		}
+		return nil
+	})
+}
+
+// GrantBadge gives the user a global badge. The definition row is locked
+// so the grant cannot race a delete of the definition.
+func (r *postgresRepository) GrantBadge(userID, slug, grantedBy string) (models.BadgeGrant, error) {
+	if r == nil || r.pool == nil {
+		return models.BadgeGrant{}, ErrPostgresUnavailable
+	}
+	var grant models.BadgeGrant
+	err := r.withTx(txSpec{Name: "badge grant", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		var exists bool
+		if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists); err != nil {
+			return fmt.Errorf("check user %s: %w", userID, err)
+		}
+		if !exists {
+			return notFound("user", userID)
+		}
+		var locked string
+		if err := tx.QueryRow(ctx, "SELECT slug FROM badge_definitions WHERE slug = $1 FOR SHARE", slug).Scan(&locked); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrBadgeNotFound
+			}
+			return fmt.Errorf("lock badge definition %s: %w", slug, err)
+		}
+		if _, err := tx.Exec(ctx, "INSERT INTO user_badges ("+badgeGrantColumns+") VALUES ($1, $2, $3, $4) ON CONFLICT (user_id, badge_slug) DO NOTHING",
+			userID, slug, grantedBy, time.Now().UTC()); err != nil {
+			return fmt.Errorf("insert badge grant: %w", err)
+		}
+		loaded, err := scanBadgeGrant(tx.QueryRow(ctx, "SELECT "+badgeGrantColumns+" FROM user_badges WHERE user_id = $1 AND badge_slug = $2", userID, slug))
+		if err != nil {
+			return fmt.Errorf("load badge grant: %w", err)
+		}
+		grant = loaded
+		return nil
+	})
+	if err != nil {
+		return models.BadgeGrant{}, err
+	}
+	return grant, nil
+}
+
+// RevokeBadge takes a global badge from the user.
+func (r *postgresRepository) RevokeBadge(userID, slug string) error {
+	if r == nil || r.pool == nil {
+		return ErrPostgresUnavailable
+	}
+	return r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		tag, err := conn.Exec(ctx, "DELETE FROM user_badges WHERE user_id = $1 AND badge_slug = $2", userID, slug)
+		if err != nil {
+			return fmt.Errorf("revoke badge %s: %w", slug, err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrBadgeGrantNotFound
+		}
+		return nil
+	})
+}
+
+// ListUserBadges returns the user's global badges, oldest grant first.
+func (r *postgresRepository) ListUserBadges(userID string) ([]models.BadgeGrant, error) {
+	if r == nil || r.pool == nil {
+		return nil, ErrPostgresUnavailable
+	}
+	var grants []models.BadgeGrant
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		var err error
+		grants, err = queryBadgeGrants(ctx, conn, "SELECT "+badgeGrantColumns+" FROM user_badges WHERE user_id = $1 ORDER BY granted_at, badge_slug", userID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
+
+// ListBadgeGrants returns every holder of the badge, oldest grant first.
+func (r *postgresRepository) ListBadgeGrants(slug string) ([]models.BadgeGrant, error) {
+	if r == nil || r.pool == nil {
+		return nil, ErrPostgresUnavailable
+	}
+	var grants []models.BadgeGrant
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		var exists bool
+		if err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM badge_definitions WHERE slug = $1)", slug).Scan(&exists); err != nil {
+			return fmt.Errorf("check badge definition %s: %w", slug, err)
+		}
+		if !exists {
+			return ErrBadgeNotFound
+		}
+		var err error
+		grants, err = queryBadgeGrants(ctx, conn, "SELECT "+badgeGrantColumns+" FROM user_badges WHERE badge_slug = $1 ORDER BY granted_at, user_id", slug)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return grants, nil
+}
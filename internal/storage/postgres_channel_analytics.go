@@ -0,0 +1,167 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"bitriver-live/internal/models"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// analyticsChannelExists reports an unknown channel the way the other
+// channel listings do.
+func analyticsChannelExists(ctx context.Context, conn *pgxpool.Conn, channelID string) error {
+	var exists bool
+	if err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM channels WHERE id = $1)", channelID).Scan(&exists); err != nil {
+		return fmt.Errorf("check channel %s: %w", channelID, err)
+	}
+	if !exists {
+		return notFound("channel", channelID)
+	}
+	return nil
+}
+
+// The analytics exports load their rows before calling fn, so a slow
+// consumer never holds a pooled connection. A year of one channel's sessions
+// or daily aggregates is small enough to keep in memory.
+
+func (r *postgresRepository) ExportChannelSessions(channelID string, from, to time.Time, fn func(ChannelSessionExportRow) error) error {
+	if r == nil || r.pool == nil {
+		return ErrPostgresUnavailable
+	}
+	if err := ValidateAnalyticsRange(from, to); err != nil {
+		return err
+	}
+	rows := make([]ChannelSessionExportRow, 0)
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		if err := analyticsChannelExists(ctx, conn, channelID); err != nil {
+			return err
+		}
+		result, err := conn.Query(ctx, "SELECT id, started_at, ended_at, peak_concurrent, COALESCE(settings->>'language', '') FROM stream_sessions WHERE channel_id = $1 AND started_at >= $2 AND started_at < $3 ORDER BY started_at ASC, id ASC", channelID, from.UTC(), to.UTC())
+		if err != nil {
+			return fmt.Errorf("export channel sessions: %w", err)
+		}
+		defer result.Close()
+		for result.Next() {
+			var (
+				row     ChannelSessionExportRow
+				endedAt pgtype.Timestamptz
+			)
+			if err := result.Scan(&row.SessionID, &row.StartedAt, &endedAt, &row.PeakConcurrent, &row.Language); err != nil {
+				return fmt.Errorf("scan channel session: %w", err)
+			}
+			row.StartedAt = row.StartedAt.UTC()
+			if endedAt.Valid {
+				ended := endedAt.Time.UTC()
+				row.EndedAt = &ended
+			}
+			rows = append(rows, row)
+		}
+		return result.Err()
+	})
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *postgresRepository) ExportChannelFollows(channelID string, from, to time.Time, fn func(ChannelFollowExportRow) error) error {
+	if r == nil || r.pool == nil {
+		return ErrPostgresUnavailable
+	}
+	if err := ValidateAnalyticsRange(from, to); err != nil {
+		return err
+	}
+	rows := make([]ChannelFollowExportRow, 0)
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		if err := analyticsChannelExists(ctx, conn, channelID); err != nil {
+			return err
+		}
+		result, err := conn.Query(ctx, "SELECT date_trunc('day', followed_at AT TIME ZONE 'UTC') AS day, COUNT(*) FROM follows WHERE channel_id = $1 AND followed_at >= $2 AND followed_at < $3 GROUP BY day ORDER BY day ASC", channelID, from.UTC(), to.UTC())
+		if err != nil {
+			return fmt.Errorf("export channel follows: %w", err)
+		}
+		defer result.Close()
+		for result.Next() {
+			var (
+				row   ChannelFollowExportRow
+				count int64
+			)
+			if err := result.Scan(&row.Day, &count); err != nil {
+				return fmt.Errorf("scan channel follows: %w", err)
+			}
+			row.Day = analyticsDay(row.Day)
+			row.Follows = int(count)
+			rows = append(rows, row)
+		}
+		return result.Err()
+	})
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *postgresRepository) ExportChannelRevenue(channelID string, from, to time.Time, fn func(ChannelRevenueExportRow) error) error {
+	if r == nil || r.pool == nil {
+		return ErrPostgresUnavailable
+	}
+	if err := ValidateAnalyticsRange(from, to); err != nil {
+		return err
+	}
+	rows := make([]ChannelRevenueExportRow, 0)
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		if err := analyticsChannelExists(ctx, conn, channelID); err != nil {
+			return err
+		}
+		result, err := conn.Query(ctx, `SELECT day, currency, SUM(tips)::bigint, SUM(tip_minor)::bigint, SUM(subscriptions)::bigint, SUM(subscription_minor)::bigint FROM (
+	SELECT date_trunc('day', created_at AT TIME ZONE 'UTC') AS day, currency, 1 AS tips, (amount * 100000000)::bigint AS tip_minor, 0 AS subscriptions, 0::bigint AS subscription_minor
+	FROM tips WHERE channel_id = $1 AND created_at >= $2 AND created_at < $3
+	UNION ALL
+	SELECT date_trunc('day', started_at AT TIME ZONE 'UTC'), currency, 0, 0::bigint, 1, (amount * 100000000)::bigint
+	FROM subscriptions WHERE channel_id = $1 AND started_at >= $2 AND started_at < $3
+) revenue GROUP BY day, currency ORDER BY day ASC, currency ASC`, channelID, from.UTC(), to.UTC())
+		if err != nil {
+			return fmt.Errorf("export channel revenue: %w", err)
+		}
+		defer result.Close()
+		for result.Next() {
+			var (
+				row                              ChannelRevenueExportRow
+				tips, subscriptions              int64
+				tipMinor, subscriptionMinorUnits int64
+			)
+			if err := result.Scan(&row.Day, &row.Currency, &tips, &tipMinor, &subscriptions, &subscriptionMinorUnits); err != nil {
+				return fmt.Errorf("scan channel revenue: %w", err)
+			}
+			row.Day = analyticsDay(row.Day)
+			row.Tips = int(tips)
+			row.TipAmount = models.NewMoneyFromMinorUnits(tipMinor)
+			row.Subscriptions = int(subscriptions)
+			row.SubscriptionAmount = models.NewMoneyFromMinorUnits(subscriptionMinorUnits)
+			rows = append(rows, row)
+		}
+		return result.Err()
+	})
+	if err != nil {
+		return err
+	}
+	for _, row := range rows {
+		if err := fn(row); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,245 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"bitriver-live/internal/models"
+)
+
+func (r *postgresRepository) grantTime() time.Time {
+	if r.grantNow != nil {
+		return r.grantNow()
+	}
+	return time.Now().UTC()
+}
+
+// upsertChannelGrantSQL inserts a grant into table ($1 channel, $2 user,
+// $3 grantor, $4 now, $5 expiry). A grant that is still active keeps its
+// grantor and grant time and only takes the new expiry; an expired one is
+// replaced outright. It returns the stored granted_by, granted_at, and
+// expires_at.
+func upsertChannelGrantSQL(table string) string {
+	return "INSERT INTO " + table + " AS g (channel_id, user_id, granted_by, granted_at, expires_at) VALUES ($1, $2, $3, $4, $5) " +
+		"ON CONFLICT (channel_id, user_id) DO UPDATE SET " +
+		"granted_by = CASE WHEN g.expires_at IS NOT NULL AND g.expires_at <= $4 THEN EXCLUDED.granted_by ELSE g.granted_by END, " +
+		"granted_at = CASE WHEN g.expires_at IS NOT NULL AND g.expires_at <= $4 THEN EXCLUDED.granted_at ELSE g.granted_at END, " +
+		"expires_at = EXCLUDED.expires_at " +
+		"RETURNING granted_by, granted_at, expires_at"
+}
+
+func grantExpiryFromPG(value pgtype.Timestamptz) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	expires := value.Time.UTC()
+	return &expires
+}
+
+// nullableGrantExpiry returns expiresAt in UTC, or nil for grants that never
+// expire.
+func nullableGrantExpiry(expiresAt *time.Time) *time.Time {
+	if expiresAt == nil || expiresAt.IsZero() {
+		return nil
+	}
+	expires := expiresAt.UTC()
+	return &expires
+}
+
+func (r *postgresRepository) GrantChannelModerator(channelID, userID, grantedBy string, expiresAt *time.Time) (models.ChannelModerator, error) {
+	if r == nil || r.pool == nil {
+		return models.ChannelModerator{}, ErrPostgresUnavailable
+	}
+	channelID = strings.TrimSpace(channelID)
+	userID = strings.TrimSpace(userID)
+	grantedBy = strings.TrimSpace(grantedBy)
+	now := r.grantTime()
+	expires, err := normalizeGrantExpiry(now, expiresAt)
+	if err != nil {
+		return models.ChannelModerator{}, err
+	}
+	var moderator models.ChannelModerator
+	err = r.withTx(txSpec{Name: "grant channel moderator", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		if err := ensureChannelExists(ctx, tx, channelID); err != nil {
+			return err
+		}
+		var found string
+		if err := tx.QueryRow(ctx, "SELECT id FROM users WHERE id = $1", userID).Scan(&found); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return notFound("user", userID)
+			}
+			return fmt.Errorf("load user %s: %w", userID, err)
+		}
+
+		var grantor pgtype.Text
+		if grantedBy != "" {
+			grantor = pgtype.Text{String: grantedBy, Valid: true}
+		}
+		var (
+			grantedAt time.Time
+			expiry    pgtype.Timestamptz
+		)
+		if err := tx.QueryRow(ctx, upsertChannelGrantSQL("channel_moderators"), channelID, userID, grantor, now, expires).Scan(&grantor, &grantedAt, &expiry); err != nil {
+			return fmt.Errorf("grant channel moderator %s: %w", userID, err)
+		}
+		moderator = models.ChannelModerator{ChannelID: channelID, UserID: userID, GrantedAt: grantedAt.UTC(), ExpiresAt: grantExpiryFromPG(expiry)}
+		if grantor.Valid {
+			moderator.GrantedBy = grantor.String
+		}
+		return nil
+	})
+	if err != nil {
+		return models.ChannelModerator{}, err
+	}
+	return moderator, nil
+}
+
+func (r *postgresRepository) RevokeChannelModerator(channelID, userID string) error {
+	if r == nil || r.pool == nil {
+		return ErrPostgresUnavailable
+	}
+	channelID = strings.TrimSpace(channelID)
+	userID = strings.TrimSpace(userID)
+	return r.withTx(txSpec{Name: "revoke channel moderator", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		if err := ensureChannelExists(ctx, tx, channelID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, "DELETE FROM channel_moderators WHERE channel_id = $1 AND user_id = $2", channelID, userID); err != nil {
+			return fmt.Errorf("revoke channel moderator %s: %w", userID, err)
+		}
+		return nil
+	})
+}
+
+func (r *postgresRepository) ListChannelModerators(channelID string) ([]models.ChannelModerator, error) {
+	if r == nil || r.pool == nil {
+		return nil, ErrPostgresUnavailable
+	}
+	moderators := make([]models.ChannelModerator, 0)
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		var exists bool
+		if err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM channels WHERE id = $1)", channelID).Scan(&exists); err != nil {
+			return fmt.Errorf("check channel %s: %w", channelID, err)
+		}
+		if !exists {
+			return notFound("channel", channelID)
+		}
+		rows, err := conn.Query(ctx, "SELECT user_id, granted_by, granted_at, expires_at FROM channel_moderators WHERE channel_id = $1 AND (expires_at IS NULL OR expires_at > $2) ORDER BY granted_at, user_id", channelID, r.grantTime())
+		if err != nil {
+			return fmt.Errorf("list channel moderators: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				moderator models.ChannelModerator
+				grantor   pgtype.Text
+				grantedAt time.Time
+				expiry    pgtype.Timestamptz
+			)
+			if err := rows.Scan(&moderator.UserID, &grantor, &grantedAt, &expiry); err != nil {
+				return fmt.Errorf("scan channel moderator: %w", err)
+			}
+			moderator.ChannelID = channelID
+			moderator.GrantedAt = grantedAt.UTC()
+			moderator.ExpiresAt = grantExpiryFromPG(expiry)
+			if grantor.Valid {
+				moderator.GrantedBy = grantor.String
+			}
+			moderators = append(moderators, moderator)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return moderators, nil
+}
+
+func (r *postgresRepository) IsChannelModerator(channelID, userID string) bool {
+	if r == nil || r.pool == nil {
+		return false
+	}
+	var exists bool
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		return conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM channel_moderators WHERE channel_id = $1 AND user_id = $2 AND (expires_at IS NULL OR expires_at > $3))", channelID, userID, r.grantTime()).Scan(&exists)
+	})
+	if err != nil {
+		return false
+	}
+	return exists
+}
+
+// PurgeExpiredChannelGrants deletes moderator and editor grants whose expiry
+// has passed and returns them so callers can announce the change.
+func (r *postgresRepository) PurgeExpiredChannelGrants() (ExpiredChannelGrants, error) {
+	if r == nil || r.pool == nil {
+		return ExpiredChannelGrants{}, ErrPostgresUnavailable
+	}
+	now := r.grantTime()
+	var expired ExpiredChannelGrants
+	err := r.withTx(txSpec{Name: "purge expired channel grants", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		expired = ExpiredChannelGrants{}
+		rows, err := tx.Query(ctx, "DELETE FROM channel_moderators WHERE expires_at <= $1 RETURNING channel_id, user_id, granted_by, granted_at, expires_at", now)
+		if err != nil {
+			return fmt.Errorf("purge channel moderators: %w", err)
+		}
+		for rows.Next() {
+			var (
+				moderator models.ChannelModerator
+				grantor   pgtype.Text
+				expiry    pgtype.Timestamptz
+			)
+			if err := rows.Scan(&moderator.ChannelID, &moderator.UserID, &grantor, &moderator.GrantedAt, &expiry); err != nil {
+				rows.Close()
+				return fmt.Errorf("scan expired channel moderator: %w", err)
+			}
+			moderator.GrantedAt = moderator.GrantedAt.UTC()
+			moderator.GrantedBy = grantor.String
+			moderator.ExpiresAt = grantExpiryFromPG(expiry)
+			expired.Moderators = append(expired.Moderators, moderator)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("purge channel moderators: %w", err)
+		}
+
+		rows, err = tx.Query(ctx, "DELETE FROM channel_editors WHERE expires_at <= $1 RETURNING channel_id, user_id, granted_by, granted_at, expires_at", now)
+		if err != nil {
+			return fmt.Errorf("purge channel editors: %w", err)
+		}
+		for rows.Next() {
+			var (
+				editor  models.ChannelEditor
+				grantor pgtype.Text
+				expiry  pgtype.Timestamptz
+			)
+			if err := rows.Scan(&editor.ChannelID, &editor.UserID, &grantor, &editor.GrantedAt, &expiry); err != nil {
+				rows.Close()
+				return fmt.Errorf("scan expired channel editor: %w", err)
+			}
+			editor.GrantedAt = editor.GrantedAt.UTC()
+			editor.GrantedBy = grantor.String
+			editor.ExpiresAt = grantExpiryFromPG(expiry)
+			expired.Editors = append(expired.Editors, editor)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("purge channel editors: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return ExpiredChannelGrants{}, err
+	}
+	sortChannelModerators(expired.Moderators)
+	sortChannelEditors(expired.Editors)
+	return expired, nil
+}
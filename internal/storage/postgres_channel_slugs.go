@@ -0,0 +1,153 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// postgresChannelSlugTaken checks slugs against channels_slug_idx and
+// channel_slug_redirects within tx, skipping the channel exceptID.
+func postgresChannelSlugTaken(ctx context.Context, tx pgx.Tx, exceptID string) func(slug string) (bool, error) {
+	return func(slug string) (bool, error) {
+		var taken bool
+		if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM channels WHERE slug = $1 AND id <> $2) OR EXISTS (SELECT 1 FROM channel_slug_redirects WHERE slug = $1 AND channel_id <> $2)", slug, exceptID).Scan(&taken); err != nil {
+			return false, fmt.Errorf("check channel slug: %w", err)
+		}
+		return taken, nil
+	}
+}
+
+func scannedSlugChangedAt(value pgtype.Timestamptz) *time.Time {
+	if !value.Valid {
+		return nil
+	}
+	ts := value.Time.UTC()
+	return &ts
+}
+
+func (r *postgresRepository) FindChannelBySlug(slug string) (ChannelSlugMatch, bool) {
+	key := channelSlugKey(slug)
+	if r == nil || r.pool == nil || key == "" {
+		return ChannelSlugMatch{}, false
+	}
+
+	var (
+		channelID  string
+		redirected bool
+	)
+	err := r.withReadConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		return conn.QueryRow(ctx, "SELECT id, FALSE FROM channels WHERE slug = $1 UNION ALL SELECT channel_id, TRUE FROM channel_slug_redirects WHERE slug = $1 ORDER BY 2 LIMIT 1", key).Scan(&channelID, &redirected)
+	})
+	if err != nil {
+		return ChannelSlugMatch{}, false
+	}
+	channel, ok := r.GetChannel(channelID)
+	if !ok {
+		return ChannelSlugMatch{}, false
+	}
+	return ChannelSlugMatch{Channel: channel, Redirected: redirected}, true
+}
+
+func (r *postgresRepository) ChangeChannelSlug(id, slug string, now time.Time) (models.Channel, error) {
+	if r == nil || r.pool == nil {
+		return models.Channel{}, ErrPostgresUnavailable
+	}
+	slug, err := NormalizeChannelSlug(slug)
+	if err != nil {
+		return models.Channel{}, err
+	}
+
+	updateErr := r.withTx(txSpec{Name: "change channel slug", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		var (
+			current   string
+			changedAt pgtype.Timestamptz
+		)
+		if err := tx.QueryRow(ctx, "SELECT slug, slug_changed_at FROM channels WHERE id = $1 FOR UPDATE", id).Scan(&current, &changedAt); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return notFound("channel", id)
+			}
+			return fmt.Errorf("load channel %s: %w", id, err)
+		}
+		if current == slug {
+			return nil
+		}
+		if err := checkChannelSlugCooldown(models.Channel{SlugChangedAt: scannedSlugChangedAt(changedAt)}, now); err != nil {
+			return err
+		}
+		taken, err := postgresChannelSlugTaken(ctx, tx, id)(slug)
+		if err != nil {
+			return err
+		}
+		if taken {
+			return ErrChannelSlugTaken
+		}
+
+		changed := now.UTC()
+		if _, err := tx.Exec(ctx, "DELETE FROM channel_slug_redirects WHERE slug = $1", slug); err != nil {
+			return fmt.Errorf("reclaim channel slug: %w", err)
+		}
+		if _, err := tx.Exec(ctx, "UPDATE channels SET slug = $1, slug_changed_at = $2, updated_at = $2 WHERE id = $3", slug, changed, id); err != nil {
+			return fmt.Errorf("update channel slug: %w", err)
+		}
+		if _, err := tx.Exec(ctx, "INSERT INTO channel_slug_redirects (slug, channel_id, created_at) VALUES ($1, $2, $3)", current, id, changed); err != nil {
+			return fmt.Errorf("insert channel slug redirect: %w", err)
+		}
+		if _, err := tx.Exec(ctx, "DELETE FROM channel_slug_redirects WHERE channel_id = $1 AND slug NOT IN (SELECT slug FROM channel_slug_redirects WHERE channel_id = $1 ORDER BY created_at DESC, slug LIMIT $2)", id, MaxChannelSlugRedirects); err != nil {
+			return fmt.Errorf("prune channel slug redirects: %w", err)
+		}
+		return nil
+	})
+	if updateErr != nil {
+		return models.Channel{}, updateErr
+	}
+	channel, ok := r.GetChannel(id)
+	if !ok {
+		return models.Channel{}, notFound("channel", id)
+	}
+	return channel, nil
+}
+
+func exportSnapshotChannelSlugRedirects(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	rows, err := tx.Query(ctx, "SELECT slug, channel_id, created_at FROM channel_slug_redirects")
+	if err != nil {
+		return fmt.Errorf("export channel slug redirects: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var redirect models.ChannelSlugRedirect
+		if err := rows.Scan(&redirect.Slug, &redirect.ChannelID, &redirect.CreatedAt); err != nil {
+			return fmt.Errorf("scan channel slug redirect: %w", err)
+		}
+		redirect.CreatedAt = redirect.CreatedAt.UTC()
+		snapshot.ChannelSlugRedirects[redirect.Slug] = redirect
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate channel slug redirects: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresRepository) importSnapshotChannelSlugRedirects(ctx context.Context, im *snapshotImporter, redirects map[string]models.ChannelSlugRedirect) error {
+	for _, key := range sortedSnapshotKeys(redirects) {
+		redirect := redirects[key]
+		slug := channelSlugKey(key)
+		_, err := im.exec(ctx, "channel_slug_redirects", slug, "INSERT INTO channel_slug_redirects (slug, channel_id, created_at) VALUES ($1, $2, $3) ON CONFLICT (slug) DO NOTHING",
+			slug,
+			strings.TrimSpace(redirect.ChannelID),
+			redirect.CreatedAt.UTC(),
+		)
+		if err != nil {
+			return fmt.Errorf("insert channel slug redirect %s: %w", slug, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"bitriver-live/internal/models"
+)
+
+// lockChannelStorage locks the channel's storage row, creating it when the
+// channel has never stored anything, and returns its usage and the quota it
+// is held to. Writers that check the quota hold this lock until they have
+// adjusted the total, so concurrent uploads cannot both squeeze into the
+// last free bytes.
+func (r *postgresRepository) lockChannelStorage(ctx context.Context, tx pgx.Tx, channelID string) (models.ChannelStorage, int64, error) {
+	if _, err := tx.Exec(ctx, "INSERT INTO channel_storage (channel_id, used_bytes, updated_at) VALUES ($1, 0, NOW()) ON CONFLICT (channel_id) DO NOTHING", channelID); err != nil {
+		return models.ChannelStorage{}, 0, fmt.Errorf("create channel storage %s: %w", channelID, err)
+	}
+	entry, err := scanChannelStorage(tx.QueryRow(ctx, "SELECT channel_id, used_bytes, quota_bytes, updated_at FROM channel_storage WHERE channel_id = $1 FOR UPDATE", channelID))
+	if err != nil {
+		return models.ChannelStorage{}, 0, fmt.Errorf("lock channel storage %s: %w", channelID, err)
+	}
+	return entry, effectiveStorageQuota(entry.QuotaBytes, r.storageQuota), nil
+}
+
+// adjustChannelStorage moves the channel's running total by delta without
+// letting it drop below zero.
+func adjustChannelStorage(ctx context.Context, tx pgx.Tx, channelID string, delta int64, now time.Time) error {
+	if delta == 0 {
+		return nil
+	}
+	if _, err := tx.Exec(ctx, "INSERT INTO channel_storage (channel_id, used_bytes, updated_at) VALUES ($1, GREATEST($2::BIGINT, 0), $3) ON CONFLICT (channel_id) DO UPDATE SET used_bytes = GREATEST(channel_storage.used_bytes + $2::BIGINT, 0), updated_at = EXCLUDED.updated_at", channelID, delta, now); err != nil {
+		return fmt.Errorf("update channel storage %s: %w", channelID, err)
+	}
+	return nil
+}
+
+// releaseOverQuotaRecordings clears the flag on the channel's over-quota
+// recordings once its usage is back within its quota, publishing them when
+// the channel auto-publishes.
+func (r *postgresRepository) releaseOverQuotaRecordings(ctx context.Context, tx pgx.Tx, channelID string, now time.Time) error {
+	var (
+		used     int64
+		override *int64
+	)
+	err := tx.QueryRow(ctx, "SELECT used_bytes, quota_bytes FROM channel_storage WHERE channel_id = $1", channelID).Scan(&used, &override)
+	if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+		return fmt.Errorf("load channel storage %s: %w", channelID, err)
+	}
+	if overStorageQuota(used, effectiveStorageQuota(override, r.storageQuota)) {
+		return nil
+	}
+	var policy string
+	if err := tx.QueryRow(ctx, "SELECT recording_policy FROM channels WHERE id = $1", channelID).Scan(&policy); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("load channel %s: %w", channelID, err)
+	}
+	if policy != models.RecordingPolicyAutoPublish {
+		if _, err := tx.Exec(ctx, "UPDATE recordings SET over_quota = FALSE WHERE channel_id = $1 AND over_quota", channelID); err != nil {
+			return fmt.Errorf("release recordings for channel %s: %w", channelID, err)
+		}
+		return nil
+	}
+	var retainUntil any
+	if deadline := r.recordingDeadline(now, true); deadline != nil {
+		retainUntil = *deadline
+	}
+	if _, err := tx.Exec(ctx, "UPDATE recordings SET over_quota = FALSE, published_at = COALESCE(published_at, $2), retain_until = CASE WHEN published_at IS NULL THEN $3 ELSE retain_until END WHERE channel_id = $1 AND over_quota", channelID, now, retainUntil); err != nil {
+		return fmt.Errorf("release recordings for channel %s: %w", channelID, err)
+	}
+	return nil
+}
+
+// freeChannelStorage subtracts size from the channel's total and releases
+// any recordings the freed space makes room for.
+func (r *postgresRepository) freeChannelStorage(ctx context.Context, tx pgx.Tx, channelID string, size int64, now time.Time) error {
+	if err := adjustChannelStorage(ctx, tx, channelID, -size, now); err != nil {
+		return err
+	}
+	return r.releaseOverQuotaRecordings(ctx, tx, channelID, now)
+}
+
+func scanChannelStorage(row pgx.Row) (models.ChannelStorage, error) {
+	var entry models.ChannelStorage
+	if err := row.Scan(&entry.ChannelID, &entry.UsedBytes, &entry.QuotaBytes, &entry.UpdatedAt); err != nil {
+		return models.ChannelStorage{}, err
+	}
+	entry.UpdatedAt = entry.UpdatedAt.UTC()
+	return entry, nil
+}
+
+// ChannelStorageUsage reports how much storage the channel uses, the quota
+// it is held to, and the recordings and uploads that make up its usage.
+func (r *postgresRepository) ChannelStorageUsage(channelID string) (ChannelStorageReport, error) {
+	if r == nil || r.pool == nil {
+		return ChannelStorageReport{}, ErrPostgresUnavailable
+	}
+	var report ChannelStorageReport
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		var exists bool
+		if err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM channels WHERE id = $1)", channelID).Scan(&exists); err != nil {
+			return fmt.Errorf("check channel %s: %w", channelID, err)
+		}
+		if !exists {
+			return notFound("channel", channelID)
+		}
+		entry, err := scanChannelStorage(conn.QueryRow(ctx, "SELECT channel_id, used_bytes, quota_bytes, updated_at FROM channel_storage WHERE channel_id = $1", channelID))
+		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("load channel storage %s: %w", channelID, err)
+		}
+		report = ChannelStorageReport{
+			ChannelID:     channelID,
+			UsedBytes:     entry.UsedBytes,
+			QuotaBytes:    effectiveStorageQuota(entry.QuotaBytes, r.storageQuota),
+			QuotaOverride: entry.QuotaBytes,
+			Items:         make([]ChannelStorageItem, 0),
+		}
+
+		rows, err := conn.Query(ctx, "SELECT id, title, size_bytes, over_quota, created_at FROM recordings WHERE channel_id = $1", channelID)
+		if err != nil {
+			return fmt.Errorf("list recordings: %w", err)
+		}
+		for rows.Next() {
+			item := ChannelStorageItem{Kind: StorageItemRecording}
+			if err := rows.Scan(&item.ID, &item.Title, &item.SizeBytes, &item.OverQuota, &item.CreatedAt); err != nil {
+				rows.Close()
+				return fmt.Errorf("scan recording: %w", err)
+			}
+			item.CreatedAt = item.CreatedAt.UTC()
+			report.Items = append(report.Items, item)
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("read recordings: %w", err)
+		}
+
+		rows, err = conn.Query(ctx, "SELECT id, title, CASE WHEN output_bytes > 0 THEN output_bytes ELSE size_bytes END, created_at FROM uploads WHERE channel_id = $1", channelID)
+		if err != nil {
+			return fmt.Errorf("list uploads: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			item := ChannelStorageItem{Kind: StorageItemUpload}
+			if err := rows.Scan(&item.ID, &item.Title, &item.SizeBytes, &item.CreatedAt); err != nil {
+				return fmt.Errorf("scan upload: %w", err)
+			}
+			item.CreatedAt = item.CreatedAt.UTC()
+			report.Items = append(report.Items, item)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return ChannelStorageReport{}, err
+	}
+	sortStorageItems(report.Items)
+	return report, nil
+}
+
+// SetChannelStorageQuota sets the admin override of the channel's storage
+// quota. Nil returns the channel to the platform default and zero lifts the
+// quota.
+func (r *postgresRepository) SetChannelStorageQuota(channelID string, quotaBytes *int64) (models.ChannelStorage, error) {
+	if r == nil || r.pool == nil {
+		return models.ChannelStorage{}, ErrPostgresUnavailable
+	}
+	quota, err := normalizeStorageQuota(quotaBytes)
+	if err != nil {
+		return models.ChannelStorage{}, err
+	}
+	var entry models.ChannelStorage
+	err = r.withTx(txSpec{Name: "channel storage quota", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		var id string
+		if err := tx.QueryRow(ctx, "SELECT id FROM channels WHERE id = $1 FOR UPDATE", channelID).Scan(&id); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return notFound("channel", channelID)
+			}
+			return fmt.Errorf("load channel %s: %w", channelID, err)
+		}
+		if _, _, err := r.lockChannelStorage(ctx, tx, channelID); err != nil {
+			return err
+		}
+		var value any
+		if quota != nil {
+			value = *quota
+		}
+		now := time.Now().UTC()
+		updated, err := scanChannelStorage(tx.QueryRow(ctx, "UPDATE channel_storage SET quota_bytes = $2, updated_at = $3 WHERE channel_id = $1 RETURNING channel_id, used_bytes, quota_bytes, updated_at", channelID, value, now))
+		if err != nil {
+			return fmt.Errorf("update channel storage %s: %w", channelID, err)
+		}
+		if err := r.releaseOverQuotaRecordings(ctx, tx, channelID, now); err != nil {
+			return err
+		}
+		entry = updated
+		return nil
+	})
+	if err != nil {
+		return models.ChannelStorage{}, err
+	}
+	return entry, nil
+}
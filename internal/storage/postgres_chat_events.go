@@ -0,0 +1,272 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+
+	"bitriver-live/internal/chat"
+)
+
+// maxChatMessageInsertRows bounds one multi-row chat message insert, keeping
+// its parameters well under Postgres's limit of 65535 per statement.
+const maxChatMessageInsertRows = 1000
+
+// ApplyChatEvents applies a batch in one transaction. Consecutive message
+// events are written with a single multi-row insert, and moderation and
+// report events are applied one at a time between those runs, so the batch
+// keeps its order and so does every channel in it. Each write runs under a
+// savepoint: a failing write is rolled back on its own, and a failed message
+// insert is retried row by row so only the offending messages fail.
+func (r *postgresRepository) ApplyChatEvents(ctx context.Context, events []chat.Event) error {
+	if r == nil || r.pool == nil {
+		return ErrPostgresUnavailable
+	}
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	if len(events) == 0 {
+		return nil
+	}
+	var errs []error
+	err := r.withTx(txSpec{Name: "apply chat events", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		errs = make([]error, len(events))
+		return applyChatEventsTx(ctx, tx, events, errs)
+	})
+	if err != nil {
+		return err
+	}
+	return chatEventsResult(errs)
+}
+
+// applyChatEventsTx applies events in order inside tx, recording each
+// event's failure in errs. It returns an error only when the transaction
+// cannot go on.
+func applyChatEventsTx(ctx context.Context, tx pgx.Tx, events []chat.Event, errs []error) error {
+	run := make([]int, 0, len(events))
+	flush := func() error {
+		if len(run) == 0 {
+			return nil
+		}
+		err := applyChatMessageRun(ctx, tx, events, run, errs)
+		run = run[:0]
+		return err
+	}
+	for i, evt := range events {
+		var apply func(pgx.Tx) error
+		switch evt.Type {
+		case chat.EventTypeMessage:
+			msg := evt.Message
+			switch {
+			case msg == nil:
+				errs[i] = fmt.Errorf("message payload missing")
+			case msg.ID == "" || msg.ChannelID == "" || msg.UserID == "":
+				errs[i] = fmt.Errorf("invalid message event")
+			default:
+				run = append(run, i)
+				if len(run) >= maxChatMessageInsertRows {
+					if err := flush(); err != nil {
+						return err
+					}
+				}
+			}
+			continue
+		case chat.EventTypeModeration:
+			if evt.Moderation == nil {
+				errs[i] = fmt.Errorf("moderation payload missing")
+				continue
+			}
+			apply = func(sp pgx.Tx) error {
+				return applyModerationEventTx(ctx, sp, *evt.Moderation, evt.Seq, evt.OccurredAt)
+			}
+		case chat.EventTypeReport:
+			if evt.Report == nil {
+				errs[i] = fmt.Errorf("report payload missing")
+				continue
+			}
+			apply = func(sp pgx.Tx) error {
+				return applyChatReportEvent(ctx, sp, *evt.Report)
+			}
+		case chat.EventTypeGift, chat.EventTypeMarker, chat.EventTypePin, chat.EventTypeModerator:
+			continue
+		default:
+			errs[i] = fmt.Errorf("unsupported chat event %q", evt.Type)
+			continue
+		}
+		// A moderation event may delete a message earlier in the batch,
+		// so the messages before it are written first.
+		if err := flush(); err != nil {
+			return err
+		}
+		var err error
+		if errs[i], err = inChatSavepoint(ctx, tx, apply); err != nil {
+			return err
+		}
+	}
+	return flush()
+}
+
+// applyChatMessageRun writes the message events at the indexes in run with
+// one insert. When the insert fails, each message is retried on its own so
+// the failure is pinned to the messages that caused it.
+func applyChatMessageRun(ctx context.Context, tx pgx.Tx, events []chat.Event, run []int, errs []error) error {
+	insertErr, err := inChatSavepoint(ctx, tx, func(sp pgx.Tx) error {
+		return insertChatMessages(ctx, sp, events, run)
+	})
+	if err != nil || insertErr == nil {
+		return err
+	}
+	for _, i := range run {
+		evt := events[i]
+		if errs[i], err = inChatSavepoint(ctx, tx, func(sp pgx.Tx) error {
+			return applyChatMessageTx(ctx, sp, *evt.Message, evt.Seq)
+		}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type chatClientMessageKey struct {
+	channelID, userID, clientMessageID string
+}
+
+// insertChatMessages upserts the messages at the indexes in run with one
+// statement. Like applyChatMessageTx, it first releases client message IDs
+// past their window and drops retried submissions whose client message ID
+// another message holds, whether that message is stored already or earlier
+// in the run. A message delivered twice in the run is written once, with
+// its last delivery.
+func insertChatMessages(ctx context.Context, tx pgx.Tx, events []chat.Event, run []int) error {
+	holders, err := chatClientMessageHolders(ctx, tx, events, run)
+	if err != nil {
+		return err
+	}
+	rows := make([]chat.Event, 0, len(run))
+	rowByID := make(map[string]int, len(run))
+	for _, i := range run {
+		evt := events[i]
+		msg := evt.Message
+		if msg.ClientMessageID != "" {
+			key := chatClientMessageKey{msg.ChannelID, msg.UserID, msg.ClientMessageID}
+			if holder, ok := holders[key]; ok && holder != msg.ID {
+				continue
+			}
+			holders[key] = msg.ID
+		}
+		if row, ok := rowByID[msg.ID]; ok {
+			rows[row] = evt
+			continue
+		}
+		rowByID[msg.ID] = len(rows)
+		rows = append(rows, evt)
+	}
+	if len(rows) == 0 {
+		return nil
+	}
+
+	const columns = 7
+	var query strings.Builder
+	query.WriteString("INSERT INTO chat_messages (id, channel_id, user_id, content, client_message_id, seq, created_at) VALUES ")
+	args := make([]any, 0, len(rows)*columns)
+	for n, evt := range rows {
+		if n > 0 {
+			query.WriteString(", ")
+		}
+		base := n * columns
+		fmt.Fprintf(&query, "($%d, $%d, $%d, $%d, $%d, $%d, $%d)", base+1, base+2, base+3, base+4, base+5, base+6, base+7)
+		msg := evt.Message
+		var clientParam any
+		if msg.ClientMessageID != "" {
+			clientParam = msg.ClientMessageID
+		}
+		args = append(args, msg.ID, msg.ChannelID, msg.UserID, msg.Content, clientParam, evt.Seq, msg.CreatedAt.UTC())
+	}
+	query.WriteString(" ON CONFLICT (id) DO UPDATE SET channel_id = EXCLUDED.channel_id, user_id = EXCLUDED.user_id, content = EXCLUDED.content, client_message_id = EXCLUDED.client_message_id, seq = EXCLUDED.seq, created_at = EXCLUDED.created_at")
+	if _, err := tx.Exec(ctx, query.String(), args...); err != nil {
+		return fmt.Errorf("persist chat message events: %w", err)
+	}
+	return nil
+}
+
+// chatClientMessageHolders releases the expired client message IDs the run
+// reuses and returns which stored message holds each remaining one.
+func chatClientMessageHolders(ctx context.Context, tx pgx.Tx, events []chat.Event, run []int) (map[chatClientMessageKey]string, error) {
+	holders := make(map[chatClientMessageKey]string)
+	var channelIDs, userIDs, clientIDs []string
+	seen := make(map[chatClientMessageKey]struct{})
+	for _, i := range run {
+		msg := events[i].Message
+		if msg.ClientMessageID == "" {
+			continue
+		}
+		key := chatClientMessageKey{msg.ChannelID, msg.UserID, msg.ClientMessageID}
+		if _, ok := seen[key]; ok {
+			continue
+		}
+		seen[key] = struct{}{}
+		channelIDs = append(channelIDs, key.channelID)
+		userIDs = append(userIDs, key.userID)
+		clientIDs = append(clientIDs, key.clientMessageID)
+	}
+	if len(clientIDs) == 0 {
+		return holders, nil
+	}
+	cutoff := time.Now().UTC().Add(-chat.ClientMessageIDWindow)
+	if _, err := tx.Exec(ctx, "UPDATE chat_messages AS m SET client_message_id = NULL FROM unnest($1::text[], $2::text[], $3::text[]) AS k(channel_id, user_id, client_message_id) WHERE m.channel_id = k.channel_id AND m.user_id = k.user_id AND m.client_message_id = k.client_message_id AND m.created_at <= $4", channelIDs, userIDs, clientIDs, cutoff); err != nil {
+		return nil, fmt.Errorf("release client message ids: %w", err)
+	}
+	rows, err := tx.Query(ctx, "SELECT m.channel_id, m.user_id, m.client_message_id, m.id FROM chat_messages AS m JOIN unnest($1::text[], $2::text[], $3::text[]) AS k(channel_id, user_id, client_message_id) ON m.channel_id = k.channel_id AND m.user_id = k.user_id AND m.client_message_id = k.client_message_id", channelIDs, userIDs, clientIDs)
+	if err != nil {
+		return nil, fmt.Errorf("check client message ids: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			key chatClientMessageKey
+			id  string
+		)
+		if err := rows.Scan(&key.channelID, &key.userID, &key.clientMessageID, &id); err != nil {
+			return nil, fmt.Errorf("scan client message id: %w", err)
+		}
+		holders[key] = id
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("check client message ids: %w", err)
+	}
+	return holders, nil
+}
+
+// inChatSavepoint runs fn under a savepoint of tx. A failure of fn is
+// returned as eventErr, with only fn's writes rolled back, unless it also
+// dooms the transaction; txErr reports that the batch cannot go on.
+func inChatSavepoint(ctx context.Context, tx pgx.Tx, fn func(pgx.Tx) error) (eventErr, txErr error) {
+	if _, err := tx.Exec(ctx, "SAVEPOINT chat_event"); err != nil {
+		return nil, fmt.Errorf("begin savepoint: %w", err)
+	}
+	if err := fn(tx); err != nil {
+		if _, rollbackErr := tx.Exec(ctx, "ROLLBACK TO SAVEPOINT chat_event"); rollbackErr != nil {
+			return nil, fmt.Errorf("roll back savepoint: %w", rollbackErr)
+		}
+		if abortsChatBatch(err) {
+			return nil, err
+		}
+		return err, nil
+	}
+	if _, err := tx.Exec(ctx, "RELEASE SAVEPOINT chat_event"); err != nil {
+		return nil, fmt.Errorf("release savepoint: %w", err)
+	}
+	return nil, nil
+}
+
+// abortsChatBatch reports whether err should fail a whole batch rather than
+// one event: conflicts that rerunning the transaction resolves, timeouts,
+// and cancellation say nothing about the event itself.
+func abortsChatBatch(err error) bool {
+	return isRetryableTxError(err) || isStatementTimeout(err) ||
+		errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded)
+}
@@ -0,0 +1,143 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"bitriver-live/internal/models"
+)
+
+const chatPinColumns = "channel_id, message_id, author_id, content, pinned_by, pinned_at"
+
+func scanChatPin(row pgx.Row) (models.ChatPin, error) {
+	var (
+		pin       models.ChatPin
+		messageID pgtype.Text
+		authorID  pgtype.Text
+		pinnedBy  pgtype.Text
+	)
+	if err := row.Scan(&pin.ChannelID, &messageID, &authorID, &pin.Content, &pinnedBy, &pin.PinnedAt); err != nil {
+		return models.ChatPin{}, err
+	}
+	pin.MessageID = messageID.String
+	pin.AuthorID = authorID.String
+	pin.PinnedBy = pinnedBy.String
+	pin.PinnedAt = pin.PinnedAt.UTC()
+	return pin, nil
+}
+
+// PinChatMessage pins a message above the channel's chat, replacing any
+// message already pinned there.
+func (r *postgresRepository) PinChatMessage(channelID string, params ChatPinParams) (models.ChatPin, error) {
+	if r == nil || r.pool == nil {
+		return models.ChatPin{}, ErrPostgresUnavailable
+	}
+	params, err := normalizeChatPinParams(params)
+	if err != nil {
+		return models.ChatPin{}, err
+	}
+	var pin models.ChatPin
+	err = r.withTx(txSpec{Name: "pin chat message", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		if err := ensureChannelExists(ctx, tx, channelID); err != nil {
+			return err
+		}
+		var userExists bool
+		if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)", params.ActorID).Scan(&userExists); err != nil {
+			return fmt.Errorf("check user %s: %w", params.ActorID, err)
+		}
+		if !userExists {
+			return notFound("user", params.ActorID)
+		}
+		var message *models.ChatMessage
+		if params.MessageID != "" {
+			found := models.ChatMessage{ID: params.MessageID, ChannelID: channelID}
+			err := tx.QueryRow(ctx, "SELECT user_id, content FROM chat_messages WHERE id = $1 AND channel_id = $2", params.MessageID, channelID).Scan(&found.UserID, &found.Content)
+			if err != nil {
+				if errors.Is(err, pgx.ErrNoRows) {
+					return notFound("chat message", params.MessageID)
+				}
+				return fmt.Errorf("load chat message %s: %w", params.MessageID, err)
+			}
+			message = &found
+		}
+		created := newChatPin(channelID, params, message, time.Now())
+		_, err := tx.Exec(ctx, "INSERT INTO chat_pins ("+chatPinColumns+") VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (channel_id) DO UPDATE SET message_id = EXCLUDED.message_id, author_id = EXCLUDED.author_id, content = EXCLUDED.content, pinned_by = EXCLUDED.pinned_by, pinned_at = EXCLUDED.pinned_at",
+			created.ChannelID,
+			pgtype.Text{String: created.MessageID, Valid: created.MessageID != ""},
+			pgtype.Text{String: created.AuthorID, Valid: created.AuthorID != ""},
+			created.Content,
+			created.PinnedBy,
+			created.PinnedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("pin chat message: %w", err)
+		}
+		pin = created
+		return nil
+	})
+	if err != nil {
+		return models.ChatPin{}, err
+	}
+	return pin, nil
+}
+
+// UnpinChatMessage removes the channel's pinned message and returns it. It
+// returns ErrChatPinNotFound when nothing is pinned.
+func (r *postgresRepository) UnpinChatMessage(channelID string) (models.ChatPin, error) {
+	if r == nil || r.pool == nil {
+		return models.ChatPin{}, ErrPostgresUnavailable
+	}
+	var pin models.ChatPin
+	err := r.withTx(txSpec{Name: "unpin chat message", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		if err := ensureChannelExists(ctx, tx, channelID); err != nil {
+			return err
+		}
+		removed, err := scanChatPin(tx.QueryRow(ctx, "DELETE FROM chat_pins WHERE channel_id = $1 RETURNING "+chatPinColumns, channelID))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrChatPinNotFound
+			}
+			return fmt.Errorf("unpin chat message: %w", err)
+		}
+		pin = removed
+		return nil
+	})
+	if err != nil {
+		return models.ChatPin{}, err
+	}
+	return pin, nil
+}
+
+// ChatPin returns the channel's pinned message, if any. It reads from a
+// replica when one is configured; live clients learn of pin changes from the
+// chat gateway instead.
+func (r *postgresRepository) ChatPin(channelID string) (models.ChatPin, bool) {
+	if r == nil || r.pool == nil {
+		return models.ChatPin{}, false
+	}
+	var (
+		pin   models.ChatPin
+		found bool
+	)
+	err := r.withReadConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		loaded, err := scanChatPin(conn.QueryRow(ctx, "SELECT "+chatPinColumns+" FROM chat_pins WHERE channel_id = $1", channelID))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil
+			}
+			return fmt.Errorf("load chat pin: %w", err)
+		}
+		pin, found = loaded, true
+		return nil
+	})
+	if err != nil {
+		return models.ChatPin{}, false
+	}
+	return pin, found
+}
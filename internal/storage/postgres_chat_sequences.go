@@ -0,0 +1,111 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"time"
+
+	"bitriver-live/internal/chat"
+	"bitriver-live/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func (r *postgresRepository) NextChatSequence(channelID string) (int64, error) {
+	if r == nil || r.pool == nil {
+		return 0, ErrPostgresUnavailable
+	}
+	var next int64
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		err := conn.QueryRow(ctx, "INSERT INTO chat_sequences (channel_id, seq) SELECT id, 1 FROM channels WHERE id = $1 ON CONFLICT (channel_id) DO UPDATE SET seq = chat_sequences.seq + 1 RETURNING seq", channelID).Scan(&next)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return notFound("channel", channelID)
+		}
+		if err != nil {
+			return fmt.Errorf("next chat sequence for %s: %w", channelID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return 0, err
+	}
+	return next, nil
+}
+
+// ChatEventsSince reads from the primary: a resuming client asks for events
+// the persistence worker may have stored moments ago.
+func (r *postgresRepository) ChatEventsSince(channelID string, afterSeq int64, limit int) ([]chat.Event, error) {
+	if r == nil || r.pool == nil {
+		return nil, ErrPostgresUnavailable
+	}
+	events := make([]chat.Event, 0)
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		var exists bool
+		if err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM channels WHERE id = $1)", channelID).Scan(&exists); err != nil {
+			return fmt.Errorf("check channel %s: %w", channelID, err)
+		}
+		if !exists {
+			return notFound("channel", channelID)
+		}
+
+		query := "SELECT id, channel_id, user_id, content, COALESCE(client_message_id, ''), seq, created_at FROM chat_messages WHERE channel_id = $1 AND seq > $2 ORDER BY seq"
+		args := []any{channelID, afterSeq}
+		if limit > 0 {
+			query += " LIMIT $3"
+			args = append(args, limit)
+		}
+		rows, err := conn.Query(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("list sequenced chat messages: %w", err)
+		}
+		for rows.Next() {
+			var message models.ChatMessage
+			var createdAt time.Time
+			if err := rows.Scan(&message.ID, &message.ChannelID, &message.UserID, &message.Content, &message.ClientMessageID, &message.Seq, &createdAt); err != nil {
+				rows.Close()
+				return fmt.Errorf("scan chat message: %w", err)
+			}
+			message.CreatedAt = createdAt.UTC()
+			events = append(events, chatEventFromMessage(message))
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("iterate sequenced chat messages: %w", err)
+		}
+
+		query = "SELECT id, channel_id, target_id, COALESCE(actor_id, ''), action, reason, metadata, seq, created_at FROM moderation_actions WHERE channel_id = $1 AND seq > $2 ORDER BY seq"
+		if limit > 0 {
+			query += " LIMIT $3"
+		}
+		rows, err = conn.Query(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("list sequenced moderation actions: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var action models.ModerationAction
+			var metadataJSON []byte
+			var createdAt time.Time
+			if err := rows.Scan(&action.ID, &action.ChannelID, &action.TargetID, &action.ActorID, &action.Action, &action.Reason, &metadataJSON, &action.Seq, &createdAt); err != nil {
+				return fmt.Errorf("scan moderation action: %w", err)
+			}
+			if len(metadataJSON) > 0 {
+				if err := json.Unmarshal(metadataJSON, &action.Metadata); err != nil {
+					return fmt.Errorf("decode moderation action %s metadata: %w", action.ID, err)
+				}
+			}
+			action.CreatedAt = createdAt.UTC()
+			if evt, ok := chatEventFromModerationAction(action); ok {
+				events = append(events, evt)
+			}
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return sortChatEventsBySeq(events, limit), nil
+}
@@ -10,21 +10,41 @@ import (
 // connection pool, orchestrates ingest behaviour, and integrates with object
 // storage backends when persisting recording metadata.
 type PostgresConfig struct {
-	DSN                 string
+	DSN string
+	// ReplicaDSNs lists read replicas of DSN. When set, lag-tolerant reads
+	// are spread over them round-robin; see withReadConn.
+	ReplicaDSNs         []string
 	MaxConnections      int32
 	MinConnections      int32
 	MaxConnLifetime     time.Duration
 	MaxConnIdleTime     time.Duration
 	HealthCheckInterval time.Duration
 	AcquireTimeout      time.Duration
+	// StatementTimeout bounds each statement run inside a repository
+	// transaction. It is shortened to whatever remains of AcquireTimeout,
+	// which covers both acquiring the connection and the work done on it.
+	StatementTimeout time.Duration
+	// TxMaxRetries is how many times a retry-safe transaction is rerun after
+	// a serialization failure or deadlock.
+	TxMaxRetries        int
 	ApplicationName     string
 	IngestController    ingest.Controller
 	IngestMaxAttempts   int
 	IngestRetryInterval time.Duration
 	IngestTimeout       time.Duration
+	StartingTimeout     time.Duration
 	RecordingRetention  RecordingRetentionPolicy
-	ObjectStorage       ObjectStorageConfig
-	RetentionClock      func() time.Time
+	// ChannelStorageQuota is the platform default storage quota per
+	// channel in bytes; zero is unlimited.
+	ChannelStorageQuota int64
+	// MaxConcurrentStreams is the platform default for how many of a
+	// user's channels may stream at once; zero is unlimited.
+	MaxConcurrentStreams int
+	ObjectStorage        ObjectStorageConfig
+	RetentionClock       func() time.Time
+	GrantClock           func() time.Time
+	SecretKey            []byte
+	EncryptionKeys       []EncryptionKey
 }
 
 func newPostgresConfig(dsn string, opts ...Option) PostgresConfig {
@@ -33,11 +53,13 @@ func newPostgresConfig(dsn string, opts ...Option) PostgresConfig {
 		IngestController:  ingest.NoopController{},
 		IngestMaxAttempts: 1,
 		IngestTimeout:     defaultIngestOperationTimeout,
+		TxMaxRetries:      defaultTxMaxRetries,
 		RecordingRetention: RecordingRetentionPolicy{
 			Published:   90 * 24 * time.Hour,
 			Unpublished: 14 * 24 * time.Hour,
 		},
 		RetentionClock: func() time.Time { return time.Now().UTC() },
+		GrantClock:     func() time.Time { return time.Now().UTC() },
 	}
 	for _, opt := range opts {
 		if opt != nil {
@@ -51,5 +73,7 @@ func newPostgresConfig(dsn string, opts ...Option) PostgresConfig {
 		cfg.IngestMaxAttempts = 1
 	}
 	cfg.IngestTimeout = normalizeIngestTimeout(cfg.IngestTimeout)
+	cfg.StartingTimeout = normalizeStartingTimeout(cfg.StartingTimeout)
+	cfg.StatementTimeout = normalizeStatementTimeout(cfg.StatementTimeout)
 	return cfg
 }
@@ -0,0 +1,341 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const (
+	coStreamInviteColumns = "i.id, i.session_id, i.host_channel_id, i.guest_channel_id, i.invited_by, i.status, i.created_at, i.responded_at, i.ended_at"
+	// coStreamLiveJoin keeps only invites whose session is still the host
+	// channel's live session.
+	coStreamLiveJoin      = "JOIN channels h ON h.id = i.host_channel_id AND h.current_session_id = i.session_id"
+	sqlStateUniqueViolate = "23505"
+)
+
+func scanCoStreamInvite(row pgx.Row) (models.CoStreamInvite, error) {
+	var (
+		invite      models.CoStreamInvite
+		respondedAt pgtype.Timestamptz
+		endedAt     pgtype.Timestamptz
+	)
+	if err := row.Scan(&invite.ID, &invite.SessionID, &invite.HostChannelID, &invite.GuestChannelID, &invite.InvitedBy, &invite.Status, &invite.CreatedAt, &respondedAt, &endedAt); err != nil {
+		return models.CoStreamInvite{}, err
+	}
+	invite.CreatedAt = invite.CreatedAt.UTC()
+	if respondedAt.Valid {
+		responded := respondedAt.Time.UTC()
+		invite.RespondedAt = &responded
+	}
+	if endedAt.Valid {
+		ended := endedAt.Time.UTC()
+		invite.EndedAt = &ended
+	}
+	return invite, nil
+}
+
+// coStreamGuestBusy reports whether the guest channel is live itself or
+// already co-streaming another live session.
+func coStreamGuestBusy(ctx context.Context, tx pgx.Tx, guestChannelID string) (bool, error) {
+	var busy bool
+	err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM channels WHERE id = $1 AND current_session_id IS NOT NULL) OR EXISTS (SELECT 1 FROM costream_invites i "+coStreamLiveJoin+" WHERE i.guest_channel_id = $1 AND i.status = $2)", guestChannelID, models.CoStreamInviteAccepted).Scan(&busy)
+	if err != nil {
+		return false, fmt.Errorf("check co-stream guest %s: %w", guestChannelID, err)
+	}
+	return busy, nil
+}
+
+func (r *postgresRepository) CreateCoStreamInvite(hostChannelID, guestChannelID, invitedBy string) (models.CoStreamInvite, error) {
+	if r == nil || r.pool == nil {
+		return models.CoStreamInvite{}, ErrPostgresUnavailable
+	}
+	guestChannelID = strings.TrimSpace(guestChannelID)
+	if guestChannelID == "" {
+		return models.CoStreamInvite{}, invalid("guestChannelId", "guestChannelId is required")
+	}
+	if guestChannelID == hostChannelID {
+		return models.CoStreamInvite{}, invalid("guestChannelId", "a channel cannot co-stream with itself")
+	}
+	id, err := generateID()
+	if err != nil {
+		return models.CoStreamInvite{}, err
+	}
+	var invite models.CoStreamInvite
+	err = r.withTx(txSpec{Name: "create co-stream invite", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		// Locking both channels in ID order serializes invites, answers,
+		// and stream starts that involve either of them.
+		rows, err := tx.Query(ctx, "SELECT id, current_session_id FROM channels WHERE id = ANY($1) ORDER BY id FOR UPDATE", []string{hostChannelID, guestChannelID})
+		if err != nil {
+			return fmt.Errorf("lock co-stream channels: %w", err)
+		}
+		sessions := make(map[string]pgtype.Text, 2)
+		for rows.Next() {
+			var channelID string
+			var session pgtype.Text
+			if err := rows.Scan(&channelID, &session); err != nil {
+				rows.Close()
+				return fmt.Errorf("scan co-stream channel: %w", err)
+			}
+			sessions[channelID] = session
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("lock co-stream channels: %w", err)
+		}
+		hostSession, ok := sessions[hostChannelID]
+		if !ok {
+			return notFound("channel", hostChannelID)
+		}
+		if _, ok := sessions[guestChannelID]; !ok {
+			return notFound("channel", guestChannelID)
+		}
+		if !hostSession.Valid {
+			return ErrChannelNotLive
+		}
+		busy, err := coStreamGuestBusy(ctx, tx, guestChannelID)
+		if err != nil {
+			return err
+		}
+		if busy {
+			return ErrCoStreamGuestBusy
+		}
+		var active int
+		if err := tx.QueryRow(ctx, "SELECT COUNT(*) FROM costream_invites WHERE session_id = $1 AND status IN ($2, $3)", hostSession.String, models.CoStreamInvitePending, models.CoStreamInviteAccepted).Scan(&active); err != nil {
+			return fmt.Errorf("count co-stream invites: %w", err)
+		}
+		if active >= MaxCoStreamGuests {
+			return ErrCoStreamGuestLimit
+		}
+		invite = models.CoStreamInvite{
+			ID:             id,
+			SessionID:      hostSession.String,
+			HostChannelID:  hostChannelID,
+			GuestChannelID: guestChannelID,
+			InvitedBy:      invitedBy,
+			Status:         models.CoStreamInvitePending,
+			CreatedAt:      time.Now().UTC(),
+		}
+		_, err = tx.Exec(ctx, "INSERT INTO costream_invites (id, session_id, host_channel_id, guest_channel_id, invited_by, status, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)", invite.ID, invite.SessionID, invite.HostChannelID, invite.GuestChannelID, invite.InvitedBy, invite.Status, invite.CreatedAt)
+		if err != nil {
+			// The partial unique index rejects a second open invite of
+			// the same guest into the session.
+			if sqlState(err) == sqlStateUniqueViolate {
+				return ErrCoStreamGuestBusy
+			}
+			return fmt.Errorf("insert co-stream invite: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.CoStreamInvite{}, err
+	}
+	return invite, nil
+}
+
+func (r *postgresRepository) GetCoStreamInvite(id string) (models.CoStreamInvite, bool) {
+	if r == nil || r.pool == nil {
+		return models.CoStreamInvite{}, false
+	}
+	var (
+		invite models.CoStreamInvite
+		found  bool
+	)
+	_ = r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		loaded, err := scanCoStreamInvite(conn.QueryRow(ctx, "SELECT "+coStreamInviteColumns+" FROM costream_invites i WHERE i.id = $1", id))
+		if err != nil {
+			return err
+		}
+		invite, found = loaded, true
+		return nil
+	})
+	return invite, found
+}
+
+func (r *postgresRepository) RespondCoStreamInvite(id string, accept bool) (models.CoStreamInvite, error) {
+	if r == nil || r.pool == nil {
+		return models.CoStreamInvite{}, ErrPostgresUnavailable
+	}
+	var invite models.CoStreamInvite
+	err := r.withTx(txSpec{Name: "respond co-stream invite", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		loaded, err := scanCoStreamInvite(tx.QueryRow(ctx, "SELECT "+coStreamInviteColumns+" FROM costream_invites i WHERE i.id = $1", id))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return notFound("co-stream invite", id)
+			}
+			return fmt.Errorf("load co-stream invite %s: %w", id, err)
+		}
+		rows, err := tx.Query(ctx, "SELECT id, current_session_id FROM channels WHERE id = ANY($1) ORDER BY id FOR UPDATE", []string{loaded.HostChannelID, loaded.GuestChannelID})
+		if err != nil {
+			return fmt.Errorf("lock co-stream channels: %w", err)
+		}
+		var hostSession pgtype.Text
+		for rows.Next() {
+			var channelID string
+			var session pgtype.Text
+			if err := rows.Scan(&channelID, &session); err != nil {
+				rows.Close()
+				return fmt.Errorf("scan co-stream channel: %w", err)
+			}
+			if channelID == loaded.HostChannelID {
+				hostSession = session
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("lock co-stream channels: %w", err)
+		}
+		// Re-read under the channel locks so a concurrent answer or stop
+		// is seen.
+		loaded, err = scanCoStreamInvite(tx.QueryRow(ctx, "SELECT "+coStreamInviteColumns+" FROM costream_invites i WHERE i.id = $1 FOR UPDATE", id))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return notFound("co-stream invite", id)
+			}
+			return fmt.Errorf("load co-stream invite %s: %w", id, err)
+		}
+		if loaded.Status != models.CoStreamInvitePending {
+			return ErrCoStreamInviteClosed
+		}
+		if !hostSession.Valid || hostSession.String != loaded.SessionID {
+			return ErrStreamSessionEnded
+		}
+		status := models.CoStreamInviteDeclined
+		if accept {
+			busy, err := coStreamGuestBusy(ctx, tx, loaded.GuestChannelID)
+			if err != nil {
+				return err
+			}
+			if busy {
+				return ErrCoStreamGuestBusy
+			}
+			status = models.CoStreamInviteAccepted
+		}
+		now := time.Now().UTC()
+		if _, err := tx.Exec(ctx, "UPDATE costream_invites SET status = $1, responded_at = $2 WHERE id = $3", status, now, id); err != nil {
+			return fmt.Errorf("update co-stream invite %s: %w", id, err)
+		}
+		loaded.Status = status
+		loaded.RespondedAt = &now
+		invite = loaded
+		return nil
+	})
+	if err != nil {
+		return models.CoStreamInvite{}, err
+	}
+	return invite, nil
+}
+
+func (r *postgresRepository) EndCoStreamInvite(id string) (models.CoStreamInvite, error) {
+	if r == nil || r.pool == nil {
+		return models.CoStreamInvite{}, ErrPostgresUnavailable
+	}
+	var invite models.CoStreamInvite
+	err := r.withTx(txSpec{Name: "end co-stream invite", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		loaded, err := scanCoStreamInvite(tx.QueryRow(ctx, "SELECT "+coStreamInviteColumns+" FROM costream_invites i WHERE i.id = $1 FOR UPDATE", id))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return notFound("co-stream invite", id)
+			}
+			return fmt.Errorf("load co-stream invite %s: %w", id, err)
+		}
+		if !loaded.Active() {
+			return ErrCoStreamInviteClosed
+		}
+		now := time.Now().UTC()
+		if _, err := tx.Exec(ctx, "UPDATE costream_invites SET status = $1, ended_at = $2 WHERE id = $3", models.CoStreamInviteRemoved, now, id); err != nil {
+			return fmt.Errorf("update co-stream invite %s: %w", id, err)
+		}
+		loaded.Status = models.CoStreamInviteRemoved
+		loaded.EndedAt = &now
+		invite = loaded
+		return nil
+	})
+	if err != nil {
+		return models.CoStreamInvite{}, err
+	}
+	return invite, nil
+}
+
+func (r *postgresRepository) ListCoStreamInvites(channelID string) ([]models.CoStreamInvite, error) {
+	return r.listCoStreamInvites("list co-stream invites", "(i.host_channel_id = $1 OR i.guest_channel_id = $1) AND i.status IN ($2, $3)", channelID, models.CoStreamInvitePending, models.CoStreamInviteAccepted)
+}
+
+func (r *postgresRepository) ListActiveCoStreams() ([]models.CoStreamInvite, error) {
+	return r.listCoStreamInvites("list active co-streams", "i.status = $1", models.CoStreamInviteAccepted)
+}
+
+func (r *postgresRepository) listCoStreamInvites(op, where string, args ...any) ([]models.CoStreamInvite, error) {
+	if r == nil || r.pool == nil {
+		return nil, ErrPostgresUnavailable
+	}
+	invites := make([]models.CoStreamInvite, 0)
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		rows, err := conn.Query(ctx, "SELECT "+coStreamInviteColumns+" FROM costream_invites i "+coStreamLiveJoin+" WHERE "+where+" ORDER BY i.created_at, i.id", args...)
+		if err != nil {
+			return fmt.Errorf("%s: %w", op, err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			invite, err := scanCoStreamInvite(rows)
+			if err != nil {
+				return fmt.Errorf("scan co-stream invite: %w", err)
+			}
+			invites = append(invites, invite)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return invites, nil
+}
+
+func exportSnapshotCoStreamInvites(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	rows, err := tx.Query(ctx, "SELECT "+coStreamInviteColumns+" FROM costream_invites i")
+	if err != nil {
+		return fmt.Errorf("export co-stream invites: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		invite, err := scanCoStreamInvite(rows)
+		if err != nil {
+			return fmt.Errorf("scan co-stream invite: %w", err)
+		}
+		snapshot.CoStreamInvites[invite.ID] = invite
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate co-stream invites: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresRepository) importSnapshotCoStreamInvites(ctx context.Context, im *snapshotImporter, invites map[string]models.CoStreamInvite) error {
+	for _, key := range sortedSnapshotKeys(invites) {
+		invite := invites[key]
+		id := snapshotRowID(invite.ID, key)
+		_, err := im.exec(ctx, "costream_invites", id, "INSERT INTO costream_invites (id, session_id, host_channel_id, guest_channel_id, invited_by, status, created_at, responded_at, ended_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) ON CONFLICT (id) DO NOTHING",
+			id,
+			strings.TrimSpace(invite.SessionID),
+			strings.TrimSpace(invite.HostChannelID),
+			strings.TrimSpace(invite.GuestChannelID),
+			invite.InvitedBy,
+			invite.Status,
+			invite.CreatedAt.UTC(),
+			invite.RespondedAt,
+			invite.EndedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("insert co-stream invite %s: %w", id, err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,255 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+)
+
+// RotateEncryptedFields seals every donation address, OAuth subject and email,
+// and restream stream key that is plaintext or sealed under a retired key again
+// under the primary encryption key. Rows are rewritten batchSize at a time,
+// each batch in its own transaction, so a large table never holds locks for
+// long and an interrupted run can simply be repeated.
+func (r *postgresRepository) RotateEncryptedFields(ctx context.Context, batchSize int) (EncryptionRotation, error) {
+	if r == nil || r.pool == nil {
+		return EncryptionRotation{}, ErrPostgresUnavailable
+	}
+	if r.secrets == nil {
+		return EncryptionRotation{}, ErrSecretKeyUnavailable
+	}
+	if batchSize <= 0 {
+		batchSize = DefaultEncryptionRotationBatchSize
+	}
+	var rotation EncryptionRotation
+	var err error
+	if rotation.DonationAddresses, err = r.rotateDonationAddresses(ctx, batchSize); err != nil {
+		return rotation, err
+	}
+	if rotation.OAuthSubjects, rotation.OAuthEmails, err = r.rotateOAuthAccounts(ctx, batchSize); err != nil {
+		return rotation, err
+	}
+	if rotation.RestreamKeys, err = r.rotateRestreamKeys(ctx, batchSize); err != nil {
+		return rotation, err
+	}
+	return rotation, nil
+}
+
+func (r *postgresRepository) rotateDonationAddresses(ctx context.Context, batchSize int) (int, error) {
+	total, after := 0, ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		var fetched, rotated int
+		var last string
+		err := r.withTx(txSpec{Name: "rotate donation addresses", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+			fetched, rotated, last = 0, 0, after
+			rows, err := tx.Query(ctx, "SELECT user_id, donation_addresses FROM profiles WHERE user_id > $1 ORDER BY user_id LIMIT $2 FOR UPDATE", after, batchSize)
+			if err != nil {
+				return err
+			}
+			type pending struct {
+				userID  string
+				payload []byte
+			}
+			var updates []pending
+			for rows.Next() {
+				var userID string
+				var payload []byte
+				if err := rows.Scan(&userID, &payload); err != nil {
+					rows.Close()
+					return err
+				}
+				fetched++
+				last = userID
+				addresses, err := decodeDonationAddresses(payload)
+				if err != nil {
+					rows.Close()
+					return err
+				}
+				changed := 0
+				for i, address := range addresses {
+					if !r.secrets.needsRotation(address.Address) {
+						continue
+					}
+					sealed, err := r.secrets.reseal(donationAddressOwner(userID), address.Address)
+					if err != nil {
+						rows.Close()
+						return fmt.Errorf("rotate donation address of user %s: %w", userID, err)
+					}
+					addresses[i].Address = sealed
+					changed++
+				}
+				if changed == 0 {
+					continue
+				}
+				encoded, err := encodeDonationAddresses(addresses)
+				if err != nil {
+					rows.Close()
+					return err
+				}
+				updates = append(updates, pending{userID: userID, payload: encoded})
+				rotated += changed
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			for _, update := range updates {
+				if _, err := tx.Exec(ctx, "UPDATE profiles SET donation_addresses = $1 WHERE user_id = $2", update.payload, update.userID); err != nil {
+					return fmt.Errorf("update donation addresses of user %s: %w", update.userID, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return total, fmt.Errorf("rotate donation addresses: %w", err)
+		}
+		total += rotated
+		if fetched < batchSize {
+			return total, nil
+		}
+		after = last
+	}
+}
+
+// rotateOAuthAccounts seals OAuth subjects and emails under the primary key.
+// A subject moves to the blind index of the primary key with it, so lookups
+// no longer need the retired key.
+func (r *postgresRepository) rotateOAuthAccounts(ctx context.Context, batchSize int) (int, int, error) {
+	subjects, emails := 0, 0
+	afterProvider, afterSubject := "", ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return subjects, emails, err
+		}
+		var fetched, rotatedSubjects, rotatedEmails int
+		var lastProvider, lastSubject string
+		err := r.withTx(txSpec{Name: "rotate oauth accounts", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+			fetched, rotatedSubjects, rotatedEmails = 0, 0, 0
+			lastProvider, lastSubject = afterProvider, afterSubject
+			rows, err := tx.Query(ctx, "SELECT provider, subject, subject_index, email FROM oauth_accounts WHERE (provider, subject) > ($1, $2) ORDER BY provider, subject LIMIT $3 FOR UPDATE", afterProvider, afterSubject, batchSize)
+			if err != nil {
+				return err
+			}
+			type pending struct {
+				provider, subject, sealedSubject, index, email string
+			}
+			var updates []pending
+			for rows.Next() {
+				var provider, subject, email string
+				var index pgtype.Text
+				if err := rows.Scan(&provider, &subject, &index, &email); err != nil {
+					rows.Close()
+					return err
+				}
+				fetched++
+				lastProvider, lastSubject = provider, subject
+				plain, err := openOAuthSubject(r.secrets, provider, subject, index.String)
+				if err != nil {
+					rows.Close()
+					return err
+				}
+				update := pending{provider: provider, subject: subject, sealedSubject: subject, index: index.String, email: email}
+				if r.secrets.needsRotation(subject) || index.String != oauthSubjectIndex(r.secrets, provider, plain) {
+					if update.sealedSubject, update.index, err = sealOAuthSubject(r.secrets, provider, plain); err != nil {
+						rows.Close()
+						return err
+					}
+					rotatedSubjects++
+				}
+				if r.secrets.needsRotation(email) {
+					if update.email, err = r.secrets.reseal(oauthEmailOwner(provider, plain), email); err != nil {
+						rows.Close()
+						return fmt.Errorf("rotate oauth email for %s account: %w", provider, err)
+					}
+					rotatedEmails++
+				}
+				if update.sealedSubject != subject || update.email != email {
+					updates = append(updates, update)
+				}
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			for _, update := range updates {
+				if _, err := tx.Exec(ctx, "UPDATE oauth_accounts SET subject = $1, subject_index = $2, email = $3 WHERE provider = $4 AND subject = $5", update.sealedSubject, update.index, update.email, update.provider, update.subject); err != nil {
+					return fmt.Errorf("update oauth account for %s: %w", update.provider, err)
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return subjects, emails, fmt.Errorf("rotate oauth accounts: %w", err)
+		}
+		subjects += rotatedSubjects
+		emails += rotatedEmails
+		if fetched < batchSize {
+			return subjects, emails, nil
+		}
+		afterProvider, afterSubject = lastProvider, lastSubject
+	}
+}
+
+func (r *postgresRepository) rotateRestreamKeys(ctx context.Context, batchSize int) (int, error) {
+	total, after := 0, ""
+	for {
+		if err := ctx.Err(); err != nil {
+			return total, err
+		}
+		var fetched, rotated int
+		var last string
+		err := r.withTx(txSpec{Name: "rotate restream keys", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+			fetched, rotated, last = 0, 0, after
+			rows, err := tx.Query(ctx, "SELECT id, stream_key_ciphertext FROM restream_targets WHERE id > $1 ORDER BY id LIMIT $2 FOR UPDATE", after, batchSize)
+			if err != nil {
+				return err
+			}
+			type pending struct {
+				id, sealed string
+			}
+			var updates []pending
+			for rows.Next() {
+				var id, ciphertext string
+				if err := rows.Scan(&id, &ciphertext); err != nil {
+					rows.Close()
+					return err
+				}
+				fetched++
+				last = id
+				if !r.secrets.needsRotation(ciphertext) {
+					continue
+				}
+				sealed, err := r.secrets.reseal(id, ciphertext)
+				if err != nil {
+					rows.Close()
+					return fmt.Errorf("rotate stream key of restream target %s: %w", id, err)
+				}
+				updates = append(updates, pending{id: id, sealed: sealed})
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return err
+			}
+			for _, update := range updates {
+				if _, err := tx.Exec(ctx, "UPDATE restream_targets SET stream_key_ciphertext = $1 WHERE id = $2", update.sealed, update.id); err != nil {
+					return fmt.Errorf("update stream key of restream target %s: %w", update.id, err)
+				}
+			}
+			rotated = len(updates)
+			return nil
+		})
+		if err != nil {
+			return total, fmt.Errorf("rotate restream keys: %w", err)
+		}
+		total += rotated
+		if fetched < batchSize {
+			return total, nil
+		}
+		after = last
+	}
+}
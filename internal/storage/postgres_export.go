@@ -0,0 +1,1231 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// DefaultSnapshotChatBatchSize bounds how many chat messages are fetched per
+// query while exporting a snapshot so large chat tables never arrive in a
+// single result set.
+const DefaultSnapshotChatBatchSize = 5000
+
+type snapshotExportConfig struct {
+	chatBatchSize int
+}
+
+// SnapshotExportOption customises ExportSnapshotFromPostgres.
+type SnapshotExportOption func(*snapshotExportConfig)
+
+// WithSnapshotChatBatchSize overrides how many chat messages are read per
+// batch. Non-positive values fall back to DefaultSnapshotChatBatchSize.
+func WithSnapshotChatBatchSize(size int) SnapshotExportOption {
+	return func(cfg *snapshotExportConfig) {
+		cfg.chatBatchSize = size
+	}
+}
+
+// ExportSnapshotFromPostgres reads every table from the Postgres repository
+// into a Snapshot. All reads share a single read-only repeatable-read
+// transaction so the export reflects one consistent point in time even while
+// the platform keeps serving traffic.
+func ExportSnapshotFromPostgres(ctx context.Context, repo Repository, opts ...SnapshotExportOption) (*Snapshot, error) {
+	pgRepo, ok := repo.(*postgresRepository)
+	if !ok {
+		return nil, fmt.Errorf("postgres repository required for snapshot export")
+	}
+	cfg := snapshotExportConfig{chatBatchSize: DefaultSnapshotChatBatchSize}
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
+	}
+	if cfg.chatBatchSize <= 0 {
+		cfg.chatBatchSize = DefaultSnapshotChatBatchSize
+	}
+	return pgRepo.exportSnapshot(ctx, cfg)
+}
+
+type snapshotTableCount struct {
+	table    string
+	expected int
+}
+
+// tables pairs each table a snapshot fills with its expected row count.
+// Badge definitions are left out because the schema migration seeds them.
+func (c SnapshotCounts) tables() []snapshotTableCount {
+	return []snapshotTableCount{
+		{"users", c.Users},
+		{"profiles", c.Profiles},
+		{"channels", c.Channels},
+		{"channel_slug_redirects", c.ChannelSlugRedirects},
+		{"follows", c.Follows},
+		{"channel_editors", c.ChannelEditors},
+		{"channel_moderators", c.ChannelModerators},
+		{"stream_sessions", c.StreamSessions},
+		{"stream_session_manifests", c.StreamSessionManifests},
+		{"recordings", c.Recordings},
+		{"recording_renditions", c.RecordingRenditions},
+		{"recording_thumbnails", c.RecordingThumbnails},
+		{"uploads", c.Uploads},
+		{"clip_exports", c.ClipExports},
+		{"chat_messages", c.ChatMessages},
+		{"chat_bans", c.ChatBans},
+		{"chat_timeouts", c.ChatTimeouts},
+		{"chat_reports", c.ChatReports},
+		{"tips", c.Tips},
+		{"subscriptions", c.Subscriptions},
+		{"oauth_accounts", c.OAuthAccounts},
+		{"api_tokens", c.APITokens},
+		{"notification_preferences", c.NotificationPreferences},
+		{"stream_markers", c.StreamMarkers},
+		{"restream_targets", c.RestreamTargets},
+		{"chat_appeals", c.ChatAppeals},
+		{"moderation_actions", c.ModerationActions},
+		{"playback_preferences", c.PlaybackPreferences},
+		{"user_badges", c.BadgeGrants},
+		{"schedule_entries", c.ScheduleEntries},
+		{"schedule_feed_tokens", c.ScheduleFeedTokens},
+		{"channel_storage", c.ChannelStorage},
+		{"chat_pins", c.ChatPins},
+		{"chat_sequences", c.ChatSequences},
+		{"security_events", c.SecurityEvents},
+		{"notifications", c.Notifications},
+		{"costream_invites", c.CoStreamInvites},
+		{"feature_flags", c.FeatureFlags},
+	}
+}
+
+// VerifySnapshotCounts compares the row counts in the Postgres repository with
+// the supplied snapshot summary, returning an error naming the first table
+// that does not match. It assumes the import started from an empty database;
+// VerifySnapshotImport checks an import into a database that already held
+// rows.
+func VerifySnapshotCounts(ctx context.Context, repo Repository, counts SnapshotCounts) error {
+	actual, err := CountSnapshotTables(ctx, repo)
+	if err != nil {
+		return err
+	}
+	for _, check := range counts.tables() {
+		if actual[check.table] != check.expected {
+			return fmt.Errorf("mismatch for %s: expected %d, got %d", check.table, check.expected, actual[check.table])
+		}
+	}
+	return nil
+}
+
+// CountSnapshotTables returns the row count of every table a snapshot
+// import fills, keyed by table name.
+func CountSnapshotTables(ctx context.Context, repo Repository) (map[string]int, error) {
+	pgRepo, ok := repo.(*postgresRepository)
+	if !ok {
+		return nil, fmt.Errorf("postgres repository required for snapshot verification")
+	}
+	if pgRepo.pool == nil {
+		return nil, ErrPostgresUnavailable
+	}
+	counts := make(map[string]int)
+	for _, check := range (SnapshotCounts{}).tables() {
+		var actual int
+		if err := pgRepo.pool.QueryRow(ctx, "SELECT COUNT(*) FROM "+check.table).Scan(&actual); err != nil {
+			return nil, fmt.Errorf("query %s: %w", check.table, err)
+		}
+		counts[check.table] = actual
+	}
+	return counts, nil
+}
+
+// VerifySnapshotImport checks that each table grew, since the before counts
+// from CountSnapshotTables, by exactly the rows the report says the import
+// inserted. It returns an error naming the first table that does not match.
+func VerifySnapshotImport(ctx context.Context, repo Repository, before map[string]int, report *SnapshotImportReport) error {
+	after, err := CountSnapshotTables(ctx, repo)
+	if err != nil {
+		return err
+	}
+	for _, check := range (SnapshotCounts{}).tables() {
+		grew := after[check.table] - before[check.table]
+		if inserted := report.Inserted(check.table); grew != inserted {
+			return fmt.Errorf("mismatch for %s: inserted %d rows, table grew by %d", check.table, inserted, grew)
+		}
+	}
+	return nil
+}
+
+func (r *postgresRepository) exportSnapshot(ctx context.Context, cfg snapshotExportConfig) (*Snapshot, error) {
+	if r == nil || r.pool == nil {
+		return nil, ErrPostgresUnavailable
+	}
+	snapshot := &Snapshot{}
+	snapshot.ensureInitialized()
+
+	err := r.withConn(func(_ context.Context, conn *pgxpool.Conn) error {
+		tx, err := conn.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
+		if err != nil {
+			return fmt.Errorf("begin snapshot export transaction: %w", err)
+		}
+		defer rollbackTx(ctx, tx)
+
+		// Set before the first query so every read observes the same snapshot.
+		if _, err := tx.Exec(ctx, "SET TRANSACTION ISOLATION LEVEL REPEATABLE READ"); err != nil {
+			return fmt.Errorf("set snapshot export isolation: %w", err)
+		}
+
+		steps := []func(context.Context, pgx.Tx, *Snapshot) error{
+			exportSnapshotUsers,
+			exportSnapshotOAuthAccounts,
+			exportSnapshotAPITokens,
+			exportSnapshotNotificationPreferences,
+			exportSnapshotPlaybackPreferences,
+			exportSnapshotBadges,
+			exportSnapshotProfiles,
+			exportSnapshotChannels,
+			exportSnapshotChannelSlugRedirects,
+			exportSnapshotFollows,
+			exportSnapshotChannelEditors,
+			exportSnapshotChannelModerators,
+			exportSnapshotStreamSessions,
+			exportSnapshotStreamMarkers,
+			exportSnapshotRestreamTargets,
+			exportSnapshotScheduleEntries,
+			exportSnapshotScheduleFeedTokens,
+			exportSnapshotRecordings,
+			exportSnapshotUploads,
+			exportSnapshotChannelStorage,
+			exportSnapshotClipExports,
+			exportSnapshotChatModeration,
+			exportSnapshotChatReports,
+			exportSnapshotChatAppeals,
+			exportSnapshotChatPins,
+			exportSnapshotChatSequences,
+			exportSnapshotModerationActions,
+			exportSnapshotTips,
+			exportSnapshotSubscriptions,
+			exportSnapshotSecurityEvents,
+			exportSnapshotNotifications,
+			exportSnapshotCoStreamInvites,
+			exportSnapshotFeatureFlags,
+		}
+		for _, step := range steps {
+			if err := step(ctx, tx, snapshot); err != nil {
+				return err
+			}
+		}
+		if err := exportSnapshotChatMessages(ctx, tx, snapshot, cfg.chatBatchSize); err != nil {
+			return err
+		}
+
+		if err := tx.Commit(ctx); err != nil {
+			return fmt.Errorf("commit snapshot export: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return snapshot, nil
+}
+
+func exportSnapshotUsers(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	rows, err := tx.Query(ctx, "SELECT id, display_name, email, roles, password_hash, self_signup, created_at, birth_date, age_confirmed_at, deactivated_at, username, username_changed_at, username_grace, max_concurrent_streams FROM users")
+	if err != nil {
+		return fmt.Errorf("export users: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		user, err := scanUser(rows)
+		if err != nil {
+			return fmt.Errorf("scan user: %w", err)
+		}
+		snapshot.Users[user.ID] = user
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate users: %w", err)
+	}
+	return nil
+}
+
+func exportSnapshotOAuthAccounts(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	rows, err := tx.Query(ctx, "SELECT provider, subject, subject_index, user_id, email, display_name, linked_at FROM oauth_accounts")
+	if err != nil {
+		return fmt.Errorf("export oauth accounts: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			account                   models.OAuthAccount
+			index, email, displayName pgtype.Text
+			linkedAt                  time.Time
+		)
+		if err := rows.Scan(&account.Provider, &account.Subject, &index, &account.UserID, &email, &displayName, &linkedAt); err != nil {
+			return fmt.Errorf("scan oauth account: %w", err)
+		}
+		if email.Valid {
+			account.Email = email.String
+		}
+		if displayName.Valid {
+			account.DisplayName = displayName.String
+		}
+		account.LinkedAt = linkedAt.UTC()
+		key := oauthAccountKey(account.Provider, account.Subject)
+		if index.Valid && index.String != "" {
+			account.SubjectIndex = index.String
+			key = oauthAccountKey(account.Provider, index.String)
+		}
+		snapshot.OAuthAccounts[key] = account
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate oauth accounts: %w", err)
+	}
+	return nil
+}
+
+func exportSnapshotAPITokens(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	rows, err := tx.Query(ctx, "SELECT id, user_id, name, scopes, token_hash, created_at, expires_at FROM api_tokens")
+	if err != nil {
+		return fmt.Errorf("export api tokens: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		token, err := scanAPIToken(rows)
+		if err != nil {
+			return fmt.Errorf("scan api token: %w", err)
+		}
+		snapshot.APITokens[token.ID] = token
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate api tokens: %w", err)
+	}
+	return nil
+}
+
+func exportSnapshotNotificationPreferences(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	rows, err := tx.Query(ctx, "SELECT "+notificationPreferenceColumns+" FROM notification_preferences")
+	if err != nil {
+		return fmt.Errorf("export notification preferences: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		prefs, err := scanNotificationPreferences(rows)
+		if err != nil {
+			return fmt.Errorf("scan notification preferences: %w", err)
+		}
+		snapshot.NotificationPreferences[prefs.UserID] = prefs
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate notification preferences: %w", err)
+	}
+	return nil
+}
+
+// exportSnapshotBadges copies badge definitions and grants.
+func exportSnapshotBadges(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	rows, err := tx.Query(ctx, "SELECT "+badgeDefinitionColumns+" FROM badge_definitions")
+	if err != nil {
+		return fmt.Errorf("export badge definitions: %w", err)
+	}
+	for rows.Next() {
+		definition, err := scanBadgeDefinition(rows)
+		if err != nil {
+			rows.Close()
+			return fmt.Errorf("scan badge definition: %w", err)
+		}
+		snapshot.BadgeDefinitions[definition.Slug] = definition
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate badge definitions: %w", err)
+	}
+	grants, err := queryBadgeGrants(ctx, tx, "SELECT "+badgeGrantColumns+" FROM user_badges")
+	if err != nil {
+		return fmt.Errorf("export badge grants: %w", err)
+	}
+	for _, grant := range grants {
+		held := snapshot.BadgeGrants[grant.UserID]
+		if held == nil {
+			held = make(map[string]models.BadgeGrant)
+			snapshot.BadgeGrants[grant.UserID] = held
+		}
+		held[grant.Slug] = grant
+	}
+	return nil
+}
+
+func exportSnapshotPlaybackPreferences(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	rows, err := tx.Query(ctx, "SELECT "+playbackPreferenceColumns+" FROM playback_preferences")
+	if err != nil {
+		return fmt.Errorf("export playback preferences: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		prefs, err := scanPlaybackPreferences(rows)
+		if err != nil {
+			return fmt.Errorf("scan playback preferences: %w", err)
+		}
+		snapshot.PlaybackPreferences[prefs.UserID] = prefs
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate playback preferences: %w", err)
+	}
+	return nil
+}
+
+func exportSnapshotScheduleEntries(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	entries, err := queryScheduleEntries(ctx, tx, "SELECT "+scheduleEntryColumns+" FROM schedule_entries")
+	if err != nil {
+		return fmt.Errorf("export schedule entries: %w", err)
+	}
+	for _, entry := range entries {
+		snapshot.ScheduleEntries[entry.ID] = entry
+	}
+	return nil
+}
+
+func exportSnapshotScheduleFeedTokens(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	rows, err := tx.Query(ctx, "SELECT "+scheduleFeedTokenColumns+" FROM schedule_feed_tokens")
+	if err != nil {
+		return fmt.Errorf("export schedule feed tokens: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		token, err := scanScheduleFeedToken(rows)
+		if err != nil {
+			return fmt.Errorf("scan schedule feed token: %w", err)
+		}
+		snapshot.ScheduleFeedTokens[token.UserID] = token
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate schedule feed tokens: %w", err)
+	}
+	return nil
+}
+
+func exportSnapshotStreamMarkers(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	rows, err := tx.Query(ctx, "SELECT "+streamMarkerColumns+" FROM stream_markers")
+	if err != nil {
+		return fmt.Errorf("export stream markers: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		marker, err := scanStreamMarker(rows)
+		if err != nil {
+			return fmt.Errorf("scan stream marker: %w", err)
+		}
+		snapshot.StreamMarkers[marker.ID] = marker
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate stream markers: %w", err)
+	}
+	return nil
+}
+
+func exportSnapshotRestreamTargets(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	rows, err := tx.Query(ctx, "SELECT "+restreamTargetColumns+" FROM restream_targets")
+	if err != nil {
+		return fmt.Errorf("export restream targets: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		target, err := scanRestreamTarget(rows)
+		if err != nil {
+			return fmt.Errorf("scan restream target: %w", err)
+		}
+		snapshot.RestreamTargets[target.ID] = target
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate restream targets: %w", err)
+	}
+	return nil
+}
+
+func exportSnapshotChatAppeals(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	rows, err := tx.Query(ctx, "SELECT "+chatAppealColumns+" FROM chat_appeals")
+	if err != nil {
+		return fmt.Errorf("export chat appeals: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		appeal, err := scanChatAppeal(rows)
+		if err != nil {
+			return fmt.Errorf("scan chat appeal: %w", err)
+		}
+		snapshot.ChatAppeals[appeal.ID] = appeal
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate chat appeals: %w", err)
+	}
+	return nil
+}
+
+func exportSnapshotModerationActions(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	rows, err := tx.Query(ctx, "SELECT id, channel_id, target_id, COALESCE(actor_id, ''), action, reason, metadata, seq, created_at FROM moderation_actions")
+	if err != nil {
+		return fmt.Errorf("export moderation actions: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			action       models.ModerationAction
+			metadataJSON []byte
+			createdAt    time.Time
+		)
+		if err := rows.Scan(&action.ID, &action.ChannelID, &action.TargetID, &action.ActorID, &action.Action, &action.Reason, &metadataJSON, &action.Seq, &createdAt); err != nil {
+			return fmt.Errorf("scan moderation action: %w", err)
+		}
+		if len(metadataJSON) > 0 {
+			if err := json.Unmarshal(metadataJSON, &action.Metadata); err != nil {
+				return fmt.Errorf("decode moderation action %s metadata: %w", action.ID, err)
+			}
+		}
+		if len(action.Metadata) == 0 {
+			action.Metadata = nil
+		}
+		action.CreatedAt = createdAt.UTC()
+		snapshot.ModerationActions[action.ID] = action
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate moderation actions: %w", err)
+	}
+	return nil
+}
+
+func exportSnapshotProfiles(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	rows, err := tx.Query(ctx, "SELECT user_id, bio, avatar_url, banner_url, featured_channel_id, top_friends, social_links, donation_addresses, created_at, updated_at FROM profiles")
+	if err != nil {
+		return fmt.Errorf("export profiles: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			userID                   string
+			bio                      string
+			avatar, banner, featured pgtype.Text
+			topFriends               []string
+			socialLinksPayload       []byte
+			donationPayload          []byte
+			createdAt, updatedAt     time.Time
+		)
+		if err := rows.Scan(&userID, &bio, &avatar, &banner, &featured, &topFriends, &socialLinksPayload, &donationPayload, &createdAt, &updatedAt); err != nil {
+			return fmt.Errorf("scan profile: %w", err)
+		}
+		profile := models.Profile{
+			UserID:            userID,
+			Bio:               bio,
+			TopFriends:        append([]string{}, topFriends...),
+			SocialLinks:       []models.SocialLink{},
+			DonationAddresses: []models.CryptoAddress{},
+			CreatedAt:         createdAt.UTC(),
+			UpdatedAt:         updatedAt.UTC(),
+		}
+		if avatar.Valid {
+			profile.AvatarURL = avatar.String
+		}
+		if banner.Valid {
+			profile.BannerURL = banner.String
+		}
+		if featured.Valid && strings.TrimSpace(featured.String) != "" {
+			id := featured.String
+			profile.FeaturedChannelID = &id
+		}
+		if len(socialLinksPayload) > 0 {
+			links, err := decodeSocialLinks(socialLinksPayload)
+			if err != nil {
+				return err
+			}
+			profile.SocialLinks = links
+		}
+		if len(donationPayload) > 0 {
+			addresses, err := decodeDonationAddresses(donationPayload)
+			if err != nil {
+				return err
+			}
+			profile.DonationAddresses = addresses
+		}
+		snapshot.Profiles[userID] = profile
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate profiles: %w", err)
+	}
+	return nil
+}
+
+func exportSnapshotChannels(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	rows, err := tx.Query(ctx, "SELECT id, owner_id, stream_key_hash, stream_key_hint, title, category, tags, live_state, current_session_id, created_at, updated_at, playback_restriction, playback_previews, recording_policy, mature_content, transcode_limits, starting_since, chat_max_caps_percent, chat_restrict_links, chat_subscribers_only, chat_welcome_message, language, content_warnings, slug, slug_changed_at FROM channels")
+	if err != nil {
+		return fmt.Errorf("export channels: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			channel              models.Channel
+			category             pgtype.Text
+			tags                 []string
+			currentSession       pgtype.Text
+			createdAt, updatedAt time.Time
+			limits               []byte
+			startingSince        pgtype.Timestamptz
+			contentWarnings      []string
+			slugChangedAt        pgtype.Timestamptz
+		)
+		if err := rows.Scan(&channel.ID, &channel.OwnerID, &channel.StreamKeyHash, &channel.StreamKeyHint, &channel.Title, &category, &tags, &channel.LiveState, &currentSession, &createdAt, &updatedAt, &channel.PlaybackRestriction, &channel.PlaybackPreviews, &channel.RecordingPolicy, &channel.MatureContent, &limits, &startingSince, &channel.ChatMaxCapsPercent, &channel.ChatRestrictLinks, &channel.ChatSubscribersOnly, &channel.ChatWelcomeMessage, &channel.Language, &contentWarnings, &channel.Slug, &slugChangedAt); err != nil {
+			return fmt.Errorf("scan channel: %w", err)
+		}
+		channel.Tags = append([]string{}, tags...)
+		channel.ContentWarnings = scannedContentWarnings(contentWarnings)
+		channel.SlugChangedAt = scannedSlugChangedAt(slugChangedAt)
+		channel.CreatedAt = createdAt.UTC()
+		channel.UpdatedAt = updatedAt.UTC()
+		if category.Valid {
+			channel.Category = category.String
+		}
+		if currentSession.Valid {
+			current := currentSession.String
+			channel.CurrentSessionID = &current
+		}
+		decoded, err := decodeTranscodeLimits(limits)
+		if err != nil {
+			return err
+		}
+		channel.TranscodeLimits = decoded
+		if startingSince.Valid {
+			ts := startingSince.Time.UTC()
+			channel.StartingSince = &ts
+		}
+		snapshot.Channels[channel.ID] = channel
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate channels: %w", err)
+	}
+	return nil
+}
+
+func exportSnapshotFollows(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	rows, err := tx.Query(ctx, "SELECT user_id, channel_id, followed_at FROM follows")
+	if err != nil {
+		return fmt.Errorf("export follows: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var userID, channelID string
+		var followedAt time.Time
+		if err := rows.Scan(&userID, &channelID, &followedAt); err != nil {
+			return fmt.Errorf("scan follow: %w", err)
+		}
+		if snapshot.Follows[userID] == nil {
+			snapshot.Follows[userID] = make(map[string]time.Time)
+		}
+		snapshot.Follows[userID][channelID] = followedAt.UTC()
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate follows: %w", err)
+	}
+	return nil
+}
+
+func exportSnapshotChannelEditors(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	rows, err := tx.Query(ctx, "SELECT channel_id, user_id, granted_by, granted_at, expires_at FROM channel_editors")
+	if err != nil {
+		return fmt.Errorf("export channel editors: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			editor    models.ChannelEditor
+			grantor   pgtype.Text
+			grantedAt time.Time
+			expiresAt pgtype.Timestamptz
+		)
+		if err := rows.Scan(&editor.ChannelID, &editor.UserID, &grantor, &grantedAt, &expiresAt); err != nil {
+			return fmt.Errorf("scan channel editor: %w", err)
+		}
+		editor.GrantedAt = grantedAt.UTC()
+		if grantor.Valid {
+			editor.GrantedBy = grantor.String
+		}
+		if expiresAt.Valid {
+			expires := expiresAt.Time.UTC()
+			editor.ExpiresAt = &expires
+		}
+		if snapshot.ChannelEditors[editor.ChannelID] == nil {
+			snapshot.ChannelEditors[editor.ChannelID] = make(map[string]models.ChannelEditor)
+		}
+		snapshot.ChannelEditors[editor.ChannelID][editor.UserID] = editor
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate channel editors: %w", err)
+	}
+	return nil
+}
+
+func exportSnapshotChannelModerators(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	rows, err := tx.Query(ctx, "SELECT channel_id, user_id, granted_by, granted_at, expires_at FROM channel_moderators")
+	if err != nil {
+		return fmt.Errorf("export channel moderators: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			moderator models.ChannelModerator
+			grantor   pgtype.Text
+			grantedAt time.Time
+			expiresAt pgtype.Timestamptz
+		)
+		if err := rows.Scan(&moderator.ChannelID, &moderator.UserID, &grantor, &grantedAt, &expiresAt); err != nil {
+			return fmt.Errorf("scan channel moderator: %w", err)
+		}
+		moderator.GrantedAt = grantedAt.UTC()
+		if grantor.Valid {
+			moderator.GrantedBy = grantor.String
+		}
+		if expiresAt.Valid {
+			expires := expiresAt.Time.UTC()
+			moderator.ExpiresAt = &expires
+		}
+		if snapshot.ChannelModerators[moderator.ChannelID] == nil {
+			snapshot.ChannelModerators[moderator.ChannelID] = make(map[string]models.ChannelModerator)
+		}
+		snapshot.ChannelModerators[moderator.ChannelID][moderator.UserID] = moderator
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate channel moderators: %w", err)
+	}
+	return nil
+}
+
+func exportSnapshotStreamSessions(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	rows, err := tx.Query(ctx, "SELECT id, channel_id, started_at, ended_at, renditions, peak_concurrent, origin_url, playback_url, ingest_endpoints, ingest_job_ids, preview_url, settings, playback_endpoints, receiving_media, media_confirmed_at, interrupted_at, publish_source FROM stream_sessions")
+	if err != nil {
+		return fmt.Errorf("export stream sessions: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			session         models.StreamSession
+			startedAt       time.Time
+			endedAt         pgtype.Timestamptz
+			renditions      []string
+			ingestEndpoints []string
+			ingestJobIDs    []string
+			settingsBytes   []byte
+			endpointsBytes  []byte
+			receivingMedia  bool
+			confirmedAt     pgtype.Timestamptz
+			interruptedAt   pgtype.Timestamptz
+		)
+		if err := rows.Scan(&session.ID, &session.ChannelID, &startedAt, &endedAt, &renditions, &session.PeakConcurrent, &session.OriginURL, &session.PlaybackURL, &ingestEndpoints, &ingestJobIDs, &session.PreviewURL, &settingsBytes, &endpointsBytes, &receivingMedia, &confirmedAt, &interruptedAt, &session.PublishSource); err != nil {
+			return fmt.Errorf("scan stream session: %w", err)
+		}
+		settings, err := decodeStreamSettings(settingsBytes)
+		if err != nil {
+			return fmt.Errorf("stream session %s: %w", session.ID, err)
+		}
+		session.Settings = settings
+		endpoints, err := decodePlaybackEndpoints(endpointsBytes)
+		if err != nil {
+			return fmt.Errorf("stream session %s: %w", session.ID, err)
+		}
+		session.PlaybackEndpoints = endpoints
+		setSessionMedia(&session, receivingMedia, confirmedAt, interruptedAt)
+		session.StartedAt = startedAt.UTC()
+		if endedAt.Valid {
+			ts := endedAt.Time.UTC()
+			session.EndedAt = &ts
+		}
+		session.Renditions = append([]string{}, renditions...)
+		session.IngestEndpoints = append([]string{}, ingestEndpoints...)
+		session.IngestJobIDs = append([]string{}, ingestJobIDs...)
+		session.RenditionManifests = []models.RenditionManifest{}
+		snapshot.StreamSessions[session.ID] = session
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate stream sessions: %w", err)
+	}
+	rows.Close()
+
+	manifestRows, err := tx.Query(ctx, "SELECT session_id, name, manifest_url, bitrate FROM stream_session_manifests ORDER BY session_id, name")
+	if err != nil {
+		return fmt.Errorf("export stream manifests: %w", err)
+	}
+	defer manifestRows.Close()
+	for manifestRows.Next() {
+		var sessionID, name, url string
+		var bitrate pgtype.Int4
+		if err := manifestRows.Scan(&sessionID, &name, &url, &bitrate); err != nil {
+			return fmt.Errorf("scan stream manifest: %w", err)
+		}
+		session, ok := snapshot.StreamSessions[sessionID]
+		if !ok {
+			continue
+		}
+		entry := models.RenditionManifest{Name: name, ManifestURL: url}
+		if bitrate.Valid {
+			entry.Bitrate = int(bitrate.Int32)
+		}
+		session.RenditionManifests = append(session.RenditionManifests, entry)
+		snapshot.StreamSessions[sessionID] = session
+	}
+	if err := manifestRows.Err(); err != nil {
+		return fmt.Errorf("iterate stream manifests: %w", err)
+	}
+	return nil
+}
+
+func exportSnapshotRecordings(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	rows, err := tx.Query(ctx, "SELECT id, channel_id, session_id, title, description, tags, duration_seconds, playback_base_url, metadata, published_at, created_at, retain_until, size_bytes, over_quota, chapters, premiere_at FROM recordings")
+	if err != nil {
+		return fmt.Errorf("export recordings: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			recording     models.Recording
+			tags          []string
+			metadataBytes []byte
+			publishedAt   pgtype.Timestamptz
+			createdAt     time.Time
+			retainUntil   pgtype.Timestamptz
+			chaptersBytes []byte
+			premiereAt    pgtype.Timestamptz
+		)
+		if err := rows.Scan(&recording.ID, &recording.ChannelID, &recording.SessionID, &recording.Title, &recording.Description, &tags, &recording.DurationSeconds, &recording.PlaybackBaseURL, &metadataBytes, &publishedAt, &createdAt, &retainUntil, &recording.SizeBytes, &recording.OverQuota, &chaptersBytes, &premiereAt); err != nil {
+			return fmt.Errorf("scan recording: %w", err)
+		}
+		chapters, err := decodeRecordingChapters(chaptersBytes)
+		if err != nil {
+			return fmt.Errorf("decode recording %s chapters: %w", recording.ID, err)
+		}
+		recording.Chapters = chapters
+		recording.Metadata = make(map[string]string)
+		if len(metadataBytes) > 0 {
+			if err := json.Unmarshal(metadataBytes, &recording.Metadata); err != nil {
+				return fmt.Errorf("decode recording %s metadata: %w", recording.ID, err)
+			}
+		}
+		if len(tags) > 0 {
+			recording.Tags = tags
+		}
+		recording.CreatedAt = createdAt.UTC()
+		if publishedAt.Valid {
+			ts := publishedAt.Time.UTC()
+			recording.PublishedAt = &ts
+		}
+		if retainUntil.Valid {
+			ts := retainUntil.Time.UTC()
+			recording.RetainUntil = &ts
+		}
+		if premiereAt.Valid {
+			ts := premiereAt.Time.UTC()
+			recording.PremiereAt = &ts
+		}
+		snapshot.Recordings[recording.ID] = recording
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate recordings: %w", err)
+	}
+	rows.Close()
+
+	renditionRows, err := tx.Query(ctx, "SELECT recording_id, name, manifest_url, bitrate FROM recording_renditions ORDER BY recording_id, name")
+	if err != nil {
+		return fmt.Errorf("export recording renditions: %w", err)
+	}
+	defer renditionRows.Close()
+	for renditionRows.Next() {
+		var recordingID, name, url string
+		var bitrate pgtype.Int4
+		if err := renditionRows.Scan(&recordingID, &name, &url, &bitrate); err != nil {
+			return fmt.Errorf("scan recording rendition: %w", err)
+		}
+		recording, ok := snapshot.Recordings[recordingID]
+		if !ok {
+			continue
+		}
+		entry := models.RecordingRendition{Name: name, ManifestURL: url}
+		if bitrate.Valid {
+			entry.Bitrate = int(bitrate.Int32)
+		}
+		recording.Renditions = append(recording.Renditions, entry)
+		snapshot.Recordings[recordingID] = recording
+	}
+	if err := renditionRows.Err(); err != nil {
+		return fmt.Errorf("iterate recording renditions: %w", err)
+	}
+	renditionRows.Close()
+
+	thumbRows, err := tx.Query(ctx, "SELECT id, recording_id, url, width, height, created_at FROM recording_thumbnails ORDER BY recording_id, created_at, id")
+	if err != nil {
+		return fmt.Errorf("export recording thumbnails: %w", err)
+	}
+	defer thumbRows.Close()
+	for thumbRows.Next() {
+		var thumb models.RecordingThumbnail
+		if err := thumbRows.Scan(&thumb.ID, &thumb.RecordingID, &thumb.URL, &thumb.Width, &thumb.Height, &thumb.CreatedAt); err != nil {
+			return fmt.Errorf("scan recording thumbnail: %w", err)
+		}
+		recording, ok := snapshot.Recordings[thumb.RecordingID]
+		if !ok {
+			continue
+		}
+		thumb.CreatedAt = thumb.CreatedAt.UTC()
+		recording.Thumbnails = append(recording.Thumbnails, thumb)
+		snapshot.Recordings[thumb.RecordingID] = recording
+	}
+	if err := thumbRows.Err(); err != nil {
+		return fmt.Errorf("iterate recording thumbnails: %w", err)
+	}
+	return nil
+}
+
+func exportSnapshotUploads(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	rows, err := tx.Query(ctx, "SELECT id, channel_id, title, filename, size_bytes, content_hash, status, progress, recording_id, playback_url, metadata, error, created_at, updated_at, completed_at, output_bytes, steps FROM uploads")
+	if err != nil {
+		return fmt.Errorf("export uploads: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			upload               models.Upload
+			contentHash          pgtype.Text
+			recordingID          pgtype.Text
+			playbackURL          pgtype.Text
+			metadataBytes        []byte
+			errorText            pgtype.Text
+			createdAt, updatedAt time.Time
+			completedAt          pgtype.Timestamptz
+			stepsBytes           []byte
+		)
+		if err := rows.Scan(&upload.ID, &upload.ChannelID, &upload.Title, &upload.Filename, &upload.SizeBytes, &contentHash, &upload.Status, &upload.Progress, &recordingID, &playbackURL, &metadataBytes, &errorText, &createdAt, &updatedAt, &completedAt, &upload.OutputBytes, &stepsBytes); err != nil {
+			return fmt.Errorf("scan upload: %w", err)
+		}
+		steps, err := decodeUploadSteps(stepsBytes)
+		if err != nil {
+			return fmt.Errorf("decode upload %s steps: %w", upload.ID, err)
+		}
+		upload.Steps = steps
+		upload.Metadata = make(map[string]string)
+		if len(metadataBytes) > 0 {
+			if err := json.Unmarshal(metadataBytes, &upload.Metadata); err != nil {
+				return fmt.Errorf("decode upload %s metadata: %w", upload.ID, err)
+			}
+		}
+		if contentHash.Valid {
+			upload.ContentHash = contentHash.String
+		}
+		if recordingID.Valid && strings.TrimSpace(recordingID.String) != "" {
+			value := strings.TrimSpace(recordingID.String)
+			upload.RecordingID = &value
+		}
+		if playbackURL.Valid {
+			upload.PlaybackURL = playbackURL.String
+		}
+		if errorText.Valid {
+			upload.Error = errorText.String
+		}
+		upload.CreatedAt = createdAt.UTC()
+		upload.UpdatedAt = updatedAt.UTC()
+		if completedAt.Valid {
+			ts := completedAt.Time.UTC()
+			upload.CompletedAt = &ts
+		}
+		snapshot.Uploads[upload.ID] = upload
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate uploads: %w", err)
+	}
+	return nil
+}
+
+func exportSnapshotChannelStorage(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	rows, err := tx.Query(ctx, "SELECT channel_id, used_bytes, quota_bytes, updated_at FROM channel_storage")
+	if err != nil {
+		return fmt.Errorf("export channel storage: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		entry, err := scanChannelStorage(rows)
+		if err != nil {
+			return fmt.Errorf("scan channel storage: %w", err)
+		}
+		snapshot.ChannelStorage[entry.ChannelID] = entry
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate channel storage: %w", err)
+	}
+	return nil
+}
+
+func exportSnapshotChatPins(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	rows, err := tx.Query(ctx, "SELECT "+chatPinColumns+" FROM chat_pins")
+	if err != nil {
+		return fmt.Errorf("export chat pins: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		pin, err := scanChatPin(rows)
+		if err != nil {
+			return fmt.Errorf("scan chat pin: %w", err)
+		}
+		snapshot.ChatPins[pin.ChannelID] = pin
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate chat pins: %w", err)
+	}
+	return nil
+}
+
+func exportSnapshotChatSequences(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	rows, err := tx.Query(ctx, "SELECT channel_id, seq FROM chat_sequences")
+	if err != nil {
+		return fmt.Errorf("export chat sequences: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var channelID string
+		var seq int64
+		if err := rows.Scan(&channelID, &seq); err != nil {
+			return fmt.Errorf("scan chat sequence: %w", err)
+		}
+		snapshot.ChatSequences[channelID] = seq
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate chat sequences: %w", err)
+	}
+	return nil
+}
+
+func exportSnapshotClipExports(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	rows, err := tx.Query(ctx, "SELECT id, recording_id, channel_id, session_id, title, start_seconds, end_seconds, status, playback_url, created_at, completed_at, storage_object, created_by FROM clip_exports")
+	if err != nil {
+		return fmt.Errorf("export clip exports: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			clip          models.ClipExport
+			recordingID   pgtype.Text
+			playbackURL   pgtype.Text
+			createdAt     time.Time
+			completedAt   pgtype.Timestamptz
+			storageObject pgtype.Text
+			createdBy     pgtype.Text
+		)
+		if err := rows.Scan(&clip.ID, &recordingID, &clip.ChannelID, &clip.SessionID, &clip.Title, &clip.StartSeconds, &clip.EndSeconds, &clip.Status, &playbackURL, &createdAt, &completedAt, &storageObject, &createdBy); err != nil {
+			return fmt.Errorf("scan clip export: %w", err)
+		}
+		clip.CreatedAt = createdAt.UTC()
+		if recordingID.Valid {
+			clip.RecordingID = recordingID.String
+		}
+		if createdBy.Valid {
+			clip.CreatedBy = createdBy.String
+		}
+		if playbackURL.Valid {
+			clip.PlaybackURL = playbackURL.String
+		}
+		if completedAt.Valid {
+			ts := completedAt.Time.UTC()
+			clip.CompletedAt = &ts
+		}
+		if storageObject.Valid {
+			clip.StorageObject = storageObject.String
+		}
+		snapshot.ClipExports[clip.ID] = clip
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate clip exports: %w", err)
+	}
+
+	for _, clip := range snapshot.ClipExports {
+		recording, ok := snapshot.Recordings[clip.RecordingID]
+		if !ok {
+			continue
+		}
+		recording.Clips = append(recording.Clips, models.ClipExportSummary{
+			ID:           clip.ID,
+			Title:        clip.Title,
+			StartSeconds: clip.StartSeconds,
+			EndSeconds:   clip.EndSeconds,
+			Status:       clip.Status,
+		})
+		snapshot.Recordings[clip.RecordingID] = recording
+	}
+	for id, recording := range snapshot.Recordings {
+		if len(recording.Clips) < 2 {
+			continue
+		}
+		sort.Slice(recording.Clips, func(i, j int) bool {
+			if recording.Clips[i].StartSeconds == recording.Clips[j].StartSeconds {
+				return recording.Clips[i].ID < recording.Clips[j].ID
+			}
+			return recording.Clips[i].StartSeconds < recording.Clips[j].StartSeconds
+		})
+		snapshot.Recordings[id] = recording
+	}
+	return nil
+}
+
+func exportSnapshotChatModeration(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	banRows, err := tx.Query(ctx, "SELECT channel_id, user_id, actor_id, reason, issued_at FROM chat_bans")
+	if err != nil {
+		return fmt.Errorf("export chat bans: %w", err)
+	}
+	defer banRows.Close()
+	for banRows.Next() {
+		var channelID, userID, reason string
+		var actor pgtype.Text
+		var issuedAt time.Time
+		if err := banRows.Scan(&channelID, &userID, &actor, &reason, &issuedAt); err != nil {
+			return fmt.Errorf("scan chat ban: %w", err)
+		}
+		setSnapshotTime(snapshot.ChatBans, channelID, userID, issuedAt.UTC())
+		setSnapshotString(snapshot.ChatBanActors, channelID, userID, actor.String)
+		setSnapshotString(snapshot.ChatBanReasons, channelID, userID, reason)
+	}
+	if err := banRows.Err(); err != nil {
+		return fmt.Errorf("iterate chat bans: %w", err)
+	}
+	banRows.Close()
+
+	timeoutRows, err := tx.Query(ctx, "SELECT channel_id, user_id, actor_id, reason, issued_at, expires_at FROM chat_timeouts")
+	if err != nil {
+		return fmt.Errorf("export chat timeouts: %w", err)
+	}
+	defer timeoutRows.Close()
+	for timeoutRows.Next() {
+		var channelID, userID, reason string
+		var actor pgtype.Text
+		var issuedAt, expiresAt time.Time
+		if err := timeoutRows.Scan(&channelID, &userID, &actor, &reason, &issuedAt, &expiresAt); err != nil {
+			return fmt.Errorf("scan chat timeout: %w", err)
+		}
+		setSnapshotTime(snapshot.ChatTimeouts, channelID, userID, expiresAt.UTC())
+		setSnapshotTime(snapshot.ChatTimeoutIssuedAt, channelID, userID, issuedAt.UTC())
+		setSnapshotString(snapshot.ChatTimeoutActors, channelID, userID, actor.String)
+		setSnapshotString(snapshot.ChatTimeoutReasons, channelID, userID, reason)
+	}
+	if err := timeoutRows.Err(); err != nil {
+		return fmt.Errorf("iterate chat timeouts: %w", err)
+	}
+	return nil
+}
+
+func exportSnapshotChatReports(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	rows, err := tx.Query(ctx, "SELECT "+chatReportColumns+" FROM chat_reports")
+	if err != nil {
+		return fmt.Errorf("export chat reports: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		report, err := scanChatReport(rows)
+		if err != nil {
+			return fmt.Errorf("scan chat report: %w", err)
+		}
+		snapshot.ChatReports[report.ID] = report
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate chat reports: %w", err)
+	}
+	return nil
+}
+
+func exportSnapshotTips(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	rows, err := tx.Query(ctx, "SELECT id, channel_id, from_user_id, (amount * 100000000)::bigint AS amount_minor, currency, provider, reference, wallet_address, message, created_at FROM tips")
+	if err != nil {
+		return fmt.Errorf("export tips: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var (
+			tip                    models.Tip
+			amountMinor            int64
+			walletAddress, message pgtype.Text
+			createdAt              time.Time
+		)
+		if err := rows.Scan(&tip.ID, &tip.ChannelID, &tip.FromUserID, &amountMinor, &tip.Currency, &tip.Provider, &tip.Reference, &walletAddress, &message, &createdAt); err != nil {
+			return fmt.Errorf("scan tip: %w", err)
+		}
+		tip.Amount = models.NewMoneyFromMinorUnits(amountMinor)
+		tip.WalletAddress = walletAddress.String
+		tip.Message = message.String
+		tip.CreatedAt = createdAt.UTC()
+		snapshot.Tips[tip.ID] = tip
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate tips: %w", err)
+	}
+	return nil
+}
+
+func exportSnapshotSubscriptions(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	rows, err := tx.Query(ctx, "SELECT id, channel_id, user_id, tier, provider, reference, (amount * 100000000)::bigint AS amount_minor, currency, started_at, expires_at, auto_renew, status, cancelled_by, cancelled_reason, cancelled_at, external_reference, gifter_id, is_gift FROM subscriptions")
+	if err != nil {
+		return fmt.Errorf("export subscriptions: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		sub, err := scanSubscriptionRow(rows)
+		if err != nil {
+			return fmt.Errorf("scan subscription: %w", err)
+		}
+		snapshot.Subscriptions[sub.ID] = sub
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate subscriptions: %w", err)
+	}
+	return nil
+}
+
+// exportSnapshotChatMessages pages through chat_messages by primary key so
+// only batchSize rows are buffered by the driver at any time.
+func exportSnapshotChatMessages(ctx context.Context, tx pgx.Tx, snapshot *Snapshot, batchSize int) error {
+	cursor := ""
+	for {
+		rows, err := tx.Query(ctx, "SELECT id, channel_id, user_id, content, COALESCE(client_message_id, ''), seq, created_at FROM chat_messages WHERE id > $1 ORDER BY id LIMIT $2", cursor, batchSize)
+		if err != nil {
+			return fmt.Errorf("export chat messages: %w", err)
+		}
+		fetched := 0
+		for rows.Next() {
+			var msg models.ChatMessage
+			var createdAt time.Time
+			if err := rows.Scan(&msg.ID, &msg.ChannelID, &msg.UserID, &msg.Content, &msg.ClientMessageID, &msg.Seq, &createdAt); err != nil {
+				rows.Close()
+				return fmt.Errorf("scan chat message: %w", err)
+			}
+			msg.CreatedAt = createdAt.UTC()
+			snapshot.ChatMessages[msg.ID] = msg
+			cursor = msg.ID
+			fetched++
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("iterate chat messages: %w", err)
+		}
+		if fetched < batchSize {
+			return nil
+		}
+	}
+}
+
+func setSnapshotTime(container map[string]map[string]time.Time, channelID, userID string, value time.Time) {
+	if container[channelID] == nil {
+		container[channelID] = make(map[string]time.Time)
+	}
+	container[channelID][userID] = value
+}
+
+func setSnapshotString(container map[string]map[string]string, channelID, userID, value string) {
+	if container[channelID] == nil {
+		container[channelID] = make(map[string]string)
+	}
+	container[channelID][userID] = value
+}
@@ -0,0 +1,208 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"bitriver-live/internal/models"
+)
+
+const featureFlagColumns = "key, description, enabled, rollout_percent, allow_user_ids, deny_user_ids, created_at, updated_at"
+
+func scanFeatureFlag(row pgx.Row) (models.FeatureFlag, error) {
+	var flag models.FeatureFlag
+	if err := row.Scan(&flag.Key, &flag.Description, &flag.Enabled, &flag.RolloutPercent, &flag.AllowUserIDs, &flag.DenyUserIDs, &flag.CreatedAt, &flag.UpdatedAt); err != nil {
+		return models.FeatureFlag{}, err
+	}
+	if len(flag.AllowUserIDs) == 0 {
+		flag.AllowUserIDs = nil
+	}
+	if len(flag.DenyUserIDs) == 0 {
+		flag.DenyUserIDs = nil
+	}
+	flag.CreatedAt = flag.CreatedAt.UTC()
+	flag.UpdatedAt = flag.UpdatedAt.UTC()
+	return flag, nil
+}
+
+// ListFeatureFlags returns every feature flag ordered by key.
+func (r *postgresRepository) ListFeatureFlags() ([]models.FeatureFlag, error) {
+	if r == nil || r.pool == nil {
+		return nil, ErrPostgresUnavailable
+	}
+	flags := make([]models.FeatureFlag, 0)
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		rows, err := conn.Query(ctx, "SELECT "+featureFlagColumns+" FROM feature_flags ORDER BY key")
+		if err != nil {
+			return fmt.Errorf("list feature flags: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			flag, err := scanFeatureFlag(rows)
+			if err != nil {
+				return fmt.Errorf("scan feature flag: %w", err)
+			}
+			flags = append(flags, flag)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return flags, nil
+}
+
+// GetFeatureFlag returns the flag with the given key.
+func (r *postgresRepository) GetFeatureFlag(key string) (models.FeatureFlag, bool) {
+	if r == nil || r.pool == nil {
+		return models.FeatureFlag{}, false
+	}
+	var (
+		flag  models.FeatureFlag
+		found bool
+	)
+	_ = r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		loaded, err := scanFeatureFlag(conn.QueryRow(ctx, "SELECT "+featureFlagColumns+" FROM feature_flags WHERE key = $1", key))
+		if err != nil {
+			return err
+		}
+		flag, found = loaded, true
+		return nil
+	})
+	return flag, found
+}
+
+// CreateFeatureFlag adds a feature flag. It returns ErrFeatureFlagExists
+// when the key is taken.
+func (r *postgresRepository) CreateFeatureFlag(params FeatureFlagParams) (models.FeatureFlag, error) {
+	if r == nil || r.pool == nil {
+		return models.FeatureFlag{}, ErrPostgresUnavailable
+	}
+	flag, err := newFeatureFlag(params, time.Now().UTC())
+	if err != nil {
+		return models.FeatureFlag{}, err
+	}
+	err = r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		tag, err := conn.Exec(ctx, "INSERT INTO feature_flags ("+featureFlagColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8) ON CONFLICT (key) DO NOTHING",
+			flag.Key,
+			flag.Description,
+			flag.Enabled,
+			flag.RolloutPercent,
+			nonNilStrings(flag.AllowUserIDs),
+			nonNilStrings(flag.DenyUserIDs),
+			flag.CreatedAt,
+			flag.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("insert feature flag: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrFeatureFlagExists
+		}
+		return nil
+	})
+	if err != nil {
+		return models.FeatureFlag{}, err
+	}
+	return flag, nil
+}
+
+// UpdateFeatureFlag changes a flag's description, switch, rollout, or user
+// lists.
+func (r *postgresRepository) UpdateFeatureFlag(key string, update FeatureFlagUpdate) (models.FeatureFlag, error) {
+	if r == nil || r.pool == nil {
+		return models.FeatureFlag{}, ErrPostgresUnavailable
+	}
+	var flag models.FeatureFlag
+	err := r.withTx(txSpec{Name: "feature flag", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		current, err := scanFeatureFlag(tx.QueryRow(ctx, "SELECT "+featureFlagColumns+" FROM feature_flags WHERE key = $1 FOR UPDATE", key))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrFeatureFlagNotFound
+			}
+			return fmt.Errorf("load feature flag %s: %w", key, err)
+		}
+		updated, err := applyFeatureFlagUpdate(current, update, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, "UPDATE feature_flags SET description = $1, enabled = $2, rollout_percent = $3, allow_user_ids = $4, deny_user_ids = $5, updated_at = $6 WHERE key = $7",
+			updated.Description,
+			updated.Enabled,
+			updated.RolloutPercent,
+			nonNilStrings(updated.AllowUserIDs),
+			nonNilStrings(updated.DenyUserIDs),
+			updated.UpdatedAt,
+			updated.Key,
+		); err != nil {
+			return fmt.Errorf("update feature flag %s: %w", key, err)
+		}
+		flag = updated
+		return nil
+	})
+	if err != nil {
+		return models.FeatureFlag{}, err
+	}
+	return flag, nil
+}
+
+// DeleteFeatureFlag removes a flag.
+func (r *postgresRepository) DeleteFeatureFlag(key string) error {
+	if r == nil || r.pool == nil {
+		return ErrPostgresUnavailable
+	}
+	return r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		tag, err := conn.Exec(ctx, "DELETE FROM feature_flags WHERE key = $1", key)
+		if err != nil {
+			return fmt.Errorf("delete feature flag %s: %w", key, err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrFeatureFlagNotFound
+		}
+		return nil
+	})
+}
+
+func exportSnapshotFeatureFlags(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	rows, err := tx.Query(ctx, "SELECT "+featureFlagColumns+" FROM feature_flags")
+	if err != nil {
+		return fmt.Errorf("export feature flags: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		flag, err := scanFeatureFlag(rows)
+		if err != nil {
+			return fmt.Errorf("scan feature flag: %w", err)
+		}
+		snapshot.FeatureFlags[flag.Key] = flag
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate feature flags: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresRepository) importSnapshotFeatureFlags(ctx context.Context, im *snapshotImporter, flags map[string]models.FeatureFlag) error {
+	for _, key := range sortedSnapshotKeys(flags) {
+		flag := flags[key]
+		_, err := im.exec(ctx, "feature_flags", key, "INSERT INTO feature_flags ("+featureFlagColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8) ON CONFLICT (key) DO NOTHING",
+			key,
+			flag.Description,
+			flag.Enabled,
+			flag.RolloutPercent,
+			nonNilStrings(flag.AllowUserIDs),
+			nonNilStrings(flag.DenyUserIDs),
+			flag.CreatedAt.UTC(),
+			flag.UpdatedAt.UTC(),
+		)
+		if err != nil {
+			return fmt.Errorf("insert feature flag %s: %w", key, err)
+		}
+	}
+	return nil
+}
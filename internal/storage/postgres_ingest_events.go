@@ -0,0 +1,116 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"bitriver-live/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// setSessionMedia copies the media columns scanned from stream_sessions onto
+// session.
+func setSessionMedia(session *models.StreamSession, receiving bool, confirmedAt, interruptedAt pgtype.Timestamptz) {
+	session.ReceivingMedia = receiving
+	session.MediaConfirmedAt = nil
+	session.InterruptedAt = nil
+	if confirmedAt.Valid {
+		ts := confirmedAt.Time.UTC()
+		session.MediaConfirmedAt = &ts
+	}
+	if interruptedAt.Valid {
+		ts := interruptedAt.Time.UTC()
+		session.InterruptedAt = &ts
+	}
+}
+
+func (r *postgresRepository) RecordStreamMedia(channelID string, receiving bool, at time.Time) (models.StreamSession, error) {
+	if r == nil || r.pool == nil {
+		return models.StreamSession{}, ErrPostgresUnavailable
+	}
+	var sessionID string
+	err := r.withTx(txSpec{Name: "record stream media", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		var current pgtype.Text
+		if err := tx.QueryRow(ctx, "SELECT current_session_id FROM channels WHERE id = $1", channelID).Scan(&current); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return notFound("channel", channelID)
+			}
+			return fmt.Errorf("load channel %s: %w", channelID, err)
+		}
+		if !current.Valid {
+			return ErrChannelNotLive
+		}
+		session := models.StreamSession{ID: current.String, ChannelID: channelID}
+		var (
+			wasReceiving  bool
+			confirmedAt   pgtype.Timestamptz
+			interruptedAt pgtype.Timestamptz
+		)
+		if err := tx.QueryRow(ctx, "SELECT receiving_media, media_confirmed_at, interrupted_at FROM stream_sessions WHERE id = $1 FOR UPDATE", session.ID).Scan(&wasReceiving, &confirmedAt, &interruptedAt); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Errorf("session %s missing", session.ID)
+			}
+			return fmt.Errorf("lock session %s: %w", session.ID, err)
+		}
+		setSessionMedia(&session, wasReceiving, confirmedAt, interruptedAt)
+		applyStreamMedia(&session, receiving, at)
+		if _, err := tx.Exec(ctx, "UPDATE stream_sessions SET receiving_media = $1, media_confirmed_at = $2, interrupted_at = $3 WHERE id = $4", session.ReceivingMedia, session.MediaConfirmedAt, session.InterruptedAt, session.ID); err != nil {
+			return fmt.Errorf("update session %s: %w", session.ID, err)
+		}
+		sessionID = session.ID
+		return nil
+	})
+	if err != nil {
+		return models.StreamSession{}, err
+	}
+	loadCtx, cancel := r.acquireContext()
+	defer cancel()
+	session, ok := r.loadStreamSession(loadCtx, sessionID)
+	if !ok {
+		return models.StreamSession{}, fmt.Errorf("session %s missing", sessionID)
+	}
+	return session, nil
+}
+
+func (r *postgresRepository) ListInterruptedStreamSessions(interruptedBefore time.Time) ([]models.StreamSession, error) {
+	if r == nil || r.pool == nil {
+		return nil, ErrPostgresUnavailable
+	}
+	ids := make([]string, 0)
+	if err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		rows, err := conn.Query(ctx, "SELECT s.id FROM channels c JOIN stream_sessions s ON s.id = c.current_session_id WHERE s.interrupted_at <= $1", interruptedBefore.UTC())
+		if err != nil {
+			return fmt.Errorf("list interrupted sessions: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				return fmt.Errorf("scan session id: %w", err)
+			}
+			ids = append(ids, id)
+		}
+		return rows.Err()
+	}); err != nil {
+		return nil, err
+	}
+
+	sessions := make([]models.StreamSession, 0, len(ids))
+	for _, id := range ids {
+		loadCtx, cancel := r.acquireContext()
+		session, ok := r.loadStreamSession(loadCtx, id)
+		cancel()
+		// A session stopped since the query no longer counts.
+		if !ok || session.InterruptedAt == nil {
+			continue
+		}
+		sessions = append(sessions, session)
+	}
+	sortInterruptedSessions(sessions)
+	return sessions, nil
+}
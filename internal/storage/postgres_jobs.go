@@ -0,0 +1,213 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+const jobColumns = "id, type, dedupe_key, payload, status, run_at, attempts, last_error, locked_by, locked_until, created_at, updated_at"
+
+func scanJob(row pgx.Row) (Job, error) {
+	var (
+		job         Job
+		key         pgtype.Text
+		payload     []byte
+		lastError   pgtype.Text
+		lockedBy    pgtype.Text
+		lockedUntil pgtype.Timestamptz
+	)
+	if err := row.Scan(&job.ID, &job.Type, &key, &payload, &job.Status, &job.RunAt, &job.Attempts, &lastError, &lockedBy, &lockedUntil, &job.CreatedAt, &job.UpdatedAt); err != nil {
+		return Job{}, err
+	}
+	job.Key = key.String
+	job.Payload = append([]byte(nil), payload...)
+	job.LastError = lastError.String
+	job.LockedBy = lockedBy.String
+	if lockedUntil.Valid {
+		until := lockedUntil.Time.UTC()
+		job.LockedUntil = &until
+	}
+	job.RunAt = job.RunAt.UTC()
+	job.CreatedAt = job.CreatedAt.UTC()
+	job.UpdatedAt = job.UpdatedAt.UTC()
+	return job, nil
+}
+
+func (r *postgresRepository) EnqueueJob(params EnqueueJobParams) (Job, error) {
+	if r == nil || r.pool == nil {
+		return Job{}, ErrPostgresUnavailable
+	}
+	params, err := params.normalized(time.Now().UTC())
+	if err != nil {
+		return Job{}, err
+	}
+	id, err := generateID()
+	if err != nil {
+		return Job{}, err
+	}
+	var key pgtype.Text
+	if params.Key != "" {
+		key = pgtype.Text{String: params.Key, Valid: true}
+	}
+	var job Job
+	err = r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		row := conn.QueryRow(ctx,
+			"INSERT INTO jobs (id, type, dedupe_key, payload, status, run_at, attempts, created_at, updated_at) "+
+				"VALUES ($1, $2, $3, $4, 'pending', $5, 0, NOW(), NOW()) "+
+				"ON CONFLICT (dedupe_key) WHERE dedupe_key IS NOT NULL AND status IN ('pending', 'running') DO NOTHING "+
+				"RETURNING "+jobColumns,
+			id, params.Type, key, []byte(params.Payload), params.RunAt)
+		inserted, err := scanJob(row)
+		if errors.Is(err, pgx.ErrNoRows) {
+			// A pending or running job already holds the key.
+			inserted, err = scanJob(conn.QueryRow(ctx, "SELECT "+jobColumns+" FROM jobs WHERE dedupe_key = $1 AND status IN ('pending', 'running')", key))
+		}
+		if err != nil {
+			return err
+		}
+		job = inserted
+		return nil
+	})
+	if err != nil {
+		return Job{}, fmt.Errorf("enqueue job %s: %w", params.Type, err)
+	}
+	return job, nil
+}
+
+func (r *postgresRepository) GetJob(id string) (Job, bool) {
+	if r == nil || r.pool == nil {
+		return Job{}, false
+	}
+	var job Job
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		loaded, err := scanJob(conn.QueryRow(ctx, "SELECT "+jobColumns+" FROM jobs WHERE id = $1", id))
+		job = loaded
+		return err
+	})
+	if err != nil {
+		return Job{}, false
+	}
+	return job, true
+}
+
+// ClaimDueJobs locks due rows with SKIP LOCKED so concurrent workers, even
+// in separate processes, never claim the same job.
+func (r *postgresRepository) ClaimDueJobs(workerID string, limit int, lease time.Duration) ([]Job, error) {
+	if r == nil || r.pool == nil {
+		return nil, ErrPostgresUnavailable
+	}
+	workerID = strings.TrimSpace(workerID)
+	if workerID == "" {
+		return nil, errors.New("worker id is required")
+	}
+	if lease <= 0 {
+		return nil, errors.New("job lease must be positive")
+	}
+	if limit <= 0 {
+		return nil, nil
+	}
+	var claimed []Job
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		rows, err := conn.Query(ctx,
+			"UPDATE jobs SET status = 'running', attempts = attempts + 1, locked_by = $1, "+
+				"locked_until = NOW() + make_interval(secs => $3), updated_at = NOW() "+
+				"WHERE id IN ("+
+				"SELECT id FROM jobs "+
+				"WHERE (status = 'pending' AND run_at <= NOW()) "+
+				"OR (status = 'running' AND (locked_until IS NULL OR locked_until <= NOW())) "+
+				"ORDER BY run_at, id LIMIT $2 FOR UPDATE SKIP LOCKED"+
+				") RETURNING "+jobColumns,
+			workerID, limit, lease.Seconds())
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			job, err := scanJob(rows)
+			if err != nil {
+				return err
+			}
+			claimed = append(claimed, job)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("claim jobs: %w", err)
+	}
+	sort.Slice(claimed, func(i, j int) bool {
+		if claimed[i].RunAt.Equal(claimed[j].RunAt) {
+			return claimed[i].ID < claimed[j].ID
+		}
+		return claimed[i].RunAt.Before(claimed[j].RunAt)
+	})
+	return claimed, nil
+}
+
+// jobClaimError explains why a job workerID tried to finish was not updated.
+func jobClaimError(ctx context.Context, conn *pgxpool.Conn, id string) error {
+	var exists bool
+	if err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM jobs WHERE id = $1)", id).Scan(&exists); err != nil {
+		return fmt.Errorf("check job %s: %w", id, err)
+	}
+	if !exists {
+		return notFound("job", id)
+	}
+	return ErrJobNotClaimed
+}
+
+func (r *postgresRepository) CompleteJob(id, workerID string) error {
+	if r == nil || r.pool == nil {
+		return ErrPostgresUnavailable
+	}
+	return r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		tag, err := conn.Exec(ctx, "DELETE FROM jobs WHERE id = $1 AND status = 'running' AND locked_by = $2", id, workerID)
+		if err != nil {
+			return fmt.Errorf("complete job %s: %w", id, err)
+		}
+		if tag.RowsAffected() == 0 {
+			return jobClaimError(ctx, conn, id)
+		}
+		return nil
+	})
+}
+
+func (r *postgresRepository) FailJob(id, workerID, message string, retryAt time.Time) (Job, error) {
+	if r == nil || r.pool == nil {
+		return Job{}, ErrPostgresUnavailable
+	}
+	status := JobStatusFailed
+	var runAt *time.Time
+	if !retryAt.IsZero() {
+		status = JobStatusPending
+		at := retryAt.UTC()
+		runAt = &at
+	}
+	var job Job
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		row := conn.QueryRow(ctx,
+			"UPDATE jobs SET status = $3, run_at = COALESCE($4, run_at), last_error = $5, locked_by = NULL, locked_until = NULL, updated_at = NOW() "+
+				"WHERE id = $1 AND status = 'running' AND locked_by = $2 RETURNING "+jobColumns,
+			id, workerID, status, runAt, strings.TrimSpace(message))
+		updated, err := scanJob(row)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return jobClaimError(ctx, conn, id)
+		}
+		if err != nil {
+			return fmt.Errorf("fail job %s: %w", id, err)
+		}
+		job = updated
+		return nil
+	})
+	if err != nil {
+		return Job{}, err
+	}
+	return job, nil
+}
@@ -2,83 +2,298 @@ package storage
 
 import (
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"maps"
 	"sort"
 	"strings"
 	"time"
 
+	"bitriver-live/internal/chat"
 	"bitriver-live/internal/models"
 
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
 
-func (r *postgresRepository) importSnapshot(ctx context.Context, snapshot *Snapshot) error {
+// snapshotImportStep is one resumable unit of a snapshot import. Each step
+// runs in its own transaction. Once a step imports without failed rows it is
+// recorded in snapshot_import_progress under a digest of its input, and
+// later runs over the same input skip it.
+type snapshotImportStep struct {
+	name  string
+	input func(*Snapshot) any
+	run   func(context.Context, *snapshotImporter, *Snapshot) error
+}
+
+// snapshotImportSteps lists the steps in dependency order, so rows are
+// written after the rows they reference.
+func (r *postgresRepository) snapshotImportSteps() []snapshotImportStep {
+	return []snapshotImportStep{
+		{"users", func(s *Snapshot) any { return s.Users }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotUsers(ctx, im, s.Users)
+		}},
+		{"profiles", func(s *Snapshot) any { return s.Profiles }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotProfiles(ctx, im, s.Profiles)
+		}},
+		{"channels", func(s *Snapshot) any { return []any{s.Channels, s.ChannelSlugRedirects} }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotChannels(ctx, im, s.Channels, s.ChannelSlugRedirects)
+		}},
+		{"channel_slug_redirects", func(s *Snapshot) any { return s.ChannelSlugRedirects }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotChannelSlugRedirects(ctx, im, s.ChannelSlugRedirects)
+		}},
+		{"follows", func(s *Snapshot) any { return s.Follows }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotFollows(ctx, im, s.Follows)
+		}},
+		{"channel_editors", func(s *Snapshot) any { return s.ChannelEditors }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotChannelEditors(ctx, im, s.ChannelEditors)
+		}},
+		{"channel_moderators", func(s *Snapshot) any { return s.ChannelModerators }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotChannelModerators(ctx, im, s.ChannelModerators)
+		}},
+		{"stream_sessions", func(s *Snapshot) any { return s.StreamSessions }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotStreamSessions(ctx, im, s.StreamSessions)
+		}},
+		{"stream_markers", func(s *Snapshot) any { return s.StreamMarkers }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotStreamMarkers(ctx, im, s.StreamMarkers)
+		}},
+		{"restream_targets", func(s *Snapshot) any { return s.RestreamTargets }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotRestreamTargets(ctx, im, s.RestreamTargets)
+		}},
+		{"recordings", func(s *Snapshot) any { return s.Recordings }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotRecordings(ctx, im, s.Recordings)
+		}},
+		{"uploads", func(s *Snapshot) any { return s.Uploads }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotUploads(ctx, im, s.Uploads)
+		}},
+		{"clip_exports", func(s *Snapshot) any { return s.ClipExports }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotClipExports(ctx, im, s.ClipExports)
+		}},
+		{"chat_messages", func(s *Snapshot) any { return s.ChatMessages }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotChatMessages(ctx, im, s.ChatMessages)
+		}},
+		{"chat_moderation", func(s *Snapshot) any {
+			return []any{s.ChatBans, s.ChatBanActors, s.ChatBanReasons, s.ChatTimeouts, s.ChatTimeoutActors, s.ChatTimeoutReasons, s.ChatTimeoutIssuedAt}
+		}, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotChatModeration(ctx, im, s)
+		}},
+		{"chat_reports", func(s *Snapshot) any { return s.ChatReports }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotChatReports(ctx, im, s.ChatReports)
+		}},
+		{"chat_appeals", func(s *Snapshot) any { return s.ChatAppeals }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotChatAppeals(ctx, im, s.ChatAppeals)
+		}},
+		{"moderation_actions", func(s *Snapshot) any { return s.ModerationActions }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotModerationActions(ctx, im, s.ModerationActions)
+		}},
+		{"tips", func(s *Snapshot) any { return s.Tips }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotTips(ctx, im, s.Tips)
+		}},
+		{"subscriptions", func(s *Snapshot) any { return s.Subscriptions }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotSubscriptions(ctx, im, s.Subscriptions)
+		}},
+		{"oauth_accounts", func(s *Snapshot) any { return s.OAuthAccounts }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotOAuthAccounts(ctx, im, s.OAuthAccounts)
+		}},
+		{"api_tokens", func(s *Snapshot) any { return s.APITokens }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotAPITokens(ctx, im, s.APITokens)
+		}},
+		{"notification_preferences", func(s *Snapshot) any { return s.NotificationPreferences }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotNotificationPreferences(ctx, im, s.NotificationPreferences)
+		}},
+		{"playback_preferences", func(s *Snapshot) any { return s.PlaybackPreferences }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotPlaybackPreferences(ctx, im, s.PlaybackPreferences)
+		}},
+		{"badges", func(s *Snapshot) any { return []any{s.BadgeDefinitions, s.BadgeGrants} }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotBadges(ctx, im, s.BadgeDefinitions, s.BadgeGrants)
+		}},
+		{"schedule_entries", func(s *Snapshot) any { return s.ScheduleEntries }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotScheduleEntries(ctx, im, s.ScheduleEntries)
+		}},
+		{"schedule_feed_tokens", func(s *Snapshot) any { return s.ScheduleFeedTokens }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotScheduleFeedTokens(ctx, im, s.ScheduleFeedTokens)
+		}},
+		{"channel_storage", func(s *Snapshot) any { return s.ChannelStorage }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotChannelStorage(ctx, im, s.ChannelStorage)
+		}},
+		{"chat_pins", func(s *Snapshot) any { return s.ChatPins }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotChatPins(ctx, im, s.ChatPins)
+		}},
+		{"chat_sequences", func(s *Snapshot) any { return s.ChatSequences }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotChatSequences(ctx, im, s.ChatSequences)
+		}},
+		{"security_events", func(s *Snapshot) any { return s.SecurityEvents }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotSecurityEvents(ctx, im, s.SecurityEvents)
+		}},
+		{"notifications", func(s *Snapshot) any { return s.Notifications }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotNotifications(ctx, im, s.Notifications)
+		}},
+		{"costream_invites", func(s *Snapshot) any { return s.CoStreamInvites }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotCoStreamInvites(ctx, im, s.CoStreamInvites)
+		}},
+		{"feature_flags", func(s *Snapshot) any { return s.FeatureFlags }, func(ctx context.Context, im *snapshotImporter, s *Snapshot) error {
+			return r.importSnapshotFeatureFlags(ctx, im, s.FeatureFlags)
+		}},
+	}
+}
+
+// snapshotStepDigest hashes a step's input so progress recorded for one
+// snapshot is never applied to another.
+func snapshotStepDigest(input any) (string, error) {
+	hash := sha256.New()
+	if err := json.NewEncoder(hash).Encode(input); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(hash.Sum(nil)), nil
+}
+
+// importSnapshot runs every import step. Steps that finished on an earlier
+// run over the same input are skipped. When a step fails, the steps before
+// it stay committed and the returned report covers them.
+func (r *postgresRepository) importSnapshot(ctx context.Context, snapshot *Snapshot, cfg snapshotImportConfig) (*SnapshotImportReport, error) {
 	if r == nil || r.pool == nil {
-		return ErrPostgresUnavailable
+		return nil, ErrPostgresUnavailable
 	}
-	return r.withConn(func(_ context.Context, conn *pgxpool.Conn) error {
-		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+	report := newSnapshotImportReport()
+	for _, step := range r.snapshotImportSteps() {
+		digest, err := snapshotStepDigest(step.input(snapshot))
 		if err != nil {
-			return fmt.Errorf("begin snapshot transaction: %w", err)
+			return report, fmt.Errorf("digest %s: %w", step.name, err)
 		}
-		defer rollbackTx(ctx, tx)
+		err = r.withConn(func(_ context.Context, conn *pgxpool.Conn) error {
+			tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+			if err != nil {
+				return fmt.Errorf("begin %s import: %w", step.name, err)
+			}
+			defer rollbackTx(ctx, tx)
 
-		if err := r.importSnapshotUsers(ctx, tx, snapshot.Users); err != nil {
-			return err
-		}
-		if err := r.importSnapshotProfiles(ctx, tx, snapshot.Profiles); err != nil {
-			return err
-		}
-		if err := r.importSnapshotChannels(ctx, tx, snapshot.Channels); err != nil {
-			return err
-		}
-		if err := r.importSnapshotFollows(ctx, tx, snapshot.Follows); err != nil {
-			return err
-		}
-		if err := r.importSnapshotStreamSessions(ctx, tx, snapshot.StreamSessions); err != nil {
-			return err
-		}
-		if err := r.importSnapshotRecordings(ctx, tx, snapshot.Recordings); err != nil {
-			return err
-		}
-		if err := r.importSnapshotUploads(ctx, tx, snapshot.Uploads); err != nil {
-			return err
-		}
-		if err := r.importSnapshotClipExports(ctx, tx, snapshot.ClipExports); err != nil {
-			return err
-		}
-		if err := r.importSnapshotChatMessages(ctx, tx, snapshot.ChatMessages); err != nil {
-			return err
-		}
-		if err := r.importSnapshotChatModeration(ctx, tx, snapshot); err != nil {
-			return err
-		}
-		if err := r.importSnapshotChatReports(ctx, tx, snapshot.ChatReports); err != nil {
-			return err
-		}
-		if err := r.importSnapshotTips(ctx, tx, snapshot.Tips); err != nil {
-			return err
-		}
-		if err := r.importSnapshotSubscriptions(ctx, tx, snapshot.Subscriptions); err != nil {
-			return err
-		}
-		if err := r.importSnapshotOAuthAccounts(ctx, tx, snapshot.OAuthAccounts); err != nil {
-			return err
+			var done bool
+			if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM snapshot_import_progress WHERE step = $1 AND input_digest = $2)", step.name, digest).Scan(&done); err != nil {
+				return fmt.Errorf("check %s import progress: %w", step.name, err)
+			}
+			if done {
+				report.addStep(step.name, SnapshotStepResumed, nil)
+				return nil
+			}
+
+			im := &snapshotImporter{tx: tx, continueOnError: cfg.continueOnError}
+			if err := step.run(ctx, im, snapshot); err != nil {
+				return err
+			}
+			status := SnapshotStepIncomplete
+			if len(im.failures) == 0 {
+				status = SnapshotStepImported
+				if _, err := tx.Exec(ctx, "INSERT INTO snapshot_import_progress (step, input_digest, completed_at) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING", step.name, digest, time.Now().UTC()); err != nil {
+					return fmt.Errorf("record %s import progress: %w", step.name, err)
+				}
+			}
+			if err := tx.Commit(ctx); err != nil {
+				return fmt.Errorf("commit %s import: %w", step.name, err)
+			}
+			report.addStep(step.name, status, im)
+			return nil
+		})
+		if err != nil {
+			return report, err
 		}
+	}
+	return report, nil
+}
 
-		if err := tx.Commit(ctx); err != nil {
-			return fmt.Errorf("commit snapshot import: %w", err)
+// snapshotImporter writes the rows of one import step and counts, per
+// table, how many were inserted, already present, or rejected.
+type snapshotImporter struct {
+	tx              pgx.Tx
+	continueOnError bool
+	tables          []SnapshotTableResult
+	failures        []SnapshotRowIssue
+}
+
+func (im *snapshotImporter) table(name string) *SnapshotTableResult {
+	for i := range im.tables {
+		if im.tables[i].Table == name {
+			return &im.tables[i]
 		}
-		return nil
+	}
+	im.tables = append(im.tables, SnapshotTableResult{Table: name})
+	return &im.tables[len(im.tables)-1]
+}
+
+// exec writes one row with an INSERT ... ON CONFLICT DO NOTHING statement.
+// It reports whether the row is now in the table.
+func (im *snapshotImporter) exec(ctx context.Context, table, key, sql string, args ...any) (bool, error) {
+	return im.write(ctx, table, key, func() (bool, error) {
+		tag, err := im.tx.Exec(ctx, sql, args...)
+		return tag.RowsAffected() > 0, err
+	})
+}
+
+// upsert writes one row with a statement that returns whether the row was
+// inserted rather than updated.
+func (im *snapshotImporter) upsert(ctx context.Context, table, key, sql string, args ...any) (bool, error) {
+	return im.write(ctx, table, key, func() (bool, error) {
+		var inserted bool
+		err := im.tx.QueryRow(ctx, sql, args...).Scan(&inserted)
+		return inserted, err
 	})
 }
 
-func (r *postgresRepository) importSnapshotUsers(ctx context.Context, tx pgx.Tx, users map[string]models.User) error {
+// write runs a row statement. With continueOnError the statement runs under
+// a savepoint, so a row Postgres rejects is recorded and rolled back while
+// the rest of the step carries on; write then returns false and no error.
+func (im *snapshotImporter) write(ctx context.Context, table, key string, statement func() (bool, error)) (bool, error) {
+	stats := im.table(table)
+	stats.Attempted++
+	if !im.continueOnError {
+		inserted, err := statement()
+		if err != nil {
+			return false, err
+		}
+		stats.count(inserted)
+		return true, nil
+	}
+	if _, err := im.tx.Exec(ctx, "SAVEPOINT snapshot_row"); err != nil {
+		return false, fmt.Errorf("savepoint for %s %s: %w", table, key, err)
+	}
+	inserted, err := statement()
+	if err != nil {
+		if _, rollbackErr := im.tx.Exec(ctx, "ROLLBACK TO SAVEPOINT snapshot_row"); rollbackErr != nil {
+			return false, fmt.Errorf("roll back %s %s: %w", table, key, rollbackErr)
+		}
+		stats.Failed++
+		im.failures = append(im.failures, SnapshotRowIssue{Table: table, Key: key, Problem: err.Error()})
+		return false, nil
+	}
+	if _, err := im.tx.Exec(ctx, "RELEASE SAVEPOINT snapshot_row"); err != nil {
+		return false, fmt.Errorf("release savepoint for %s %s: %w", table, key, err)
+	}
+	stats.count(inserted)
+	return true, nil
+}
+
+// reject records a row the importer could not turn into a statement. It
+// returns err unless the import continues past failed rows.
+func (im *snapshotImporter) reject(table, key string, err error) error {
+	if !im.continueOnError {
+		return err
+	}
+	stats := im.table(table)
+	stats.Attempted++
+	stats.Failed++
+	im.failures = append(im.failures, SnapshotRowIssue{Table: table, Key: key, Problem: err.Error()})
+	return nil
+}
+
+func (r *postgresRepository) importSnapshotUsers(ctx context.Context, im *snapshotImporter, users map[string]models.User) error {
 	if len(users) == 0 {
 		return nil
 	}
+	// Snapshots written before usernames existed get them assigned here,
+	// as the schema migration does for existing rows.
+	users = maps.Clone(users)
+	backfillUsernames(users)
 	ids := make([]string, 0, len(users))
 	for id := range users {
 		ids = append(ids, id)
@@ -100,7 +315,7 @@ func (r *postgresRepository) importSnapshotUsers(ctx context.Context, tx pgx.Tx,
 		if roles == nil {
 			roles = []string{}
 		}
-		_, err := tx.Exec(ctx, "INSERT INTO users (id, display_name, email, roles, password_hash, self_signup, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (id) DO NOTHING", id, strings.TrimSpace(user.DisplayName), strings.TrimSpace(user.Email), roles, strings.TrimSpace(user.PasswordHash), user.SelfSignup, createdAt)
+		_, err := im.exec(ctx, "users", id, "INSERT INTO users (id, display_name, email, roles, password_hash, self_signup, created_at, birth_date, age_confirmed_at, deactivated_at, username, username_changed_at, username_grace, max_concurrent_streams) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) ON CONFLICT (id) DO NOTHING", id, strings.TrimSpace(user.DisplayName), strings.TrimSpace(user.Email), roles, strings.TrimSpace(user.PasswordHash), user.SelfSignup, createdAt, user.BirthDate, user.AgeConfirmedAt, user.DeactivatedAt, user.Username, user.UsernameChangedAt, user.UsernameGrace, user.MaxConcurrentStreams)
 		if err != nil {
 			return fmt.Errorf("insert user %s: %w", id, err)
 		}
@@ -108,7 +323,7 @@ func (r *postgresRepository) importSnapshotUsers(ctx context.Context, tx pgx.Tx,
 	return nil
 }
 
-func (r *postgresRepository) importSnapshotProfiles(ctx context.Context, tx pgx.Tx, profiles map[string]models.Profile) error {
+func (r *postgresRepository) importSnapshotProfiles(ctx context.Context, im *snapshotImporter, profiles map[string]models.Profile) error {
 	if len(profiles) == 0 {
 		return nil
 	}
@@ -147,7 +362,7 @@ func (r *postgresRepository) importSnapshotProfiles(ctx context.Context, tx pgx.
 		if profile.FeaturedChannelID != nil && strings.TrimSpace(*profile.FeaturedChannelID) != "" {
 			featured = strings.TrimSpace(*profile.FeaturedChannelID)
 		}
-		_, err = tx.Exec(ctx, "INSERT INTO profiles (user_id, bio, avatar_url, banner_url, featured_channel_id, top_friends, social_links, donation_addresses, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) ON CONFLICT (user_id) DO NOTHING", userID, profile.Bio, strings.TrimSpace(profile.AvatarURL), strings.TrimSpace(profile.BannerURL), featured, topFriends, socialLinks, donation, created, updated)
+		_, err = im.exec(ctx, "profiles", userID, "INSERT INTO profiles (user_id, bio, avatar_url, banner_url, featured_channel_id, top_friends, social_links, donation_addresses, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) ON CONFLICT (user_id) DO NOTHING", userID, profile.Bio, strings.TrimSpace(profile.AvatarURL), strings.TrimSpace(profile.BannerURL), featured, topFriends, socialLinks, donation, created, updated)
 		if err != nil {
 			return fmt.Errorf("insert profile %s: %w", userID, err)
 		}
@@ -155,10 +370,14 @@ func (r *postgresRepository) importSnapshotProfiles(ctx context.Context, tx pgx.
 	return nil
 }
 
-func (r *postgresRepository) importSnapshotChannels(ctx context.Context, tx pgx.Tx, channels map[string]models.Channel) error {
+func (r *postgresRepository) importSnapshotChannels(ctx context.Context, im *snapshotImporter, channels map[string]models.Channel, redirects map[string]models.ChannelSlugRedirect) error {
 	if len(channels) == 0 {
 		return nil
 	}
+	// Snapshots written before channel slugs existed get them assigned
+	// here, as the schema migration does for existing rows.
+	channels = maps.Clone(channels)
+	backfillChannelSlugs(channels, redirects)
 	ids := make([]string, 0, len(channels))
 	for id := range channels {
 		ids = append(ids, id)
@@ -190,7 +409,48 @@ func (r *postgresRepository) importSnapshotChannels(ctx context.Context, tx pgx.
 		if channel.CurrentSessionID != nil && strings.TrimSpace(*channel.CurrentSessionID) != "" {
 			current = strings.TrimSpace(*channel.CurrentSessionID)
 		}
-		_, err := tx.Exec(ctx, "INSERT INTO channels (id, owner_id, stream_key, title, category, tags, live_state, current_session_id, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) ON CONFLICT (id) DO NOTHING", id, strings.TrimSpace(channel.OwnerID), strings.TrimSpace(channel.StreamKey), strings.TrimSpace(channel.Title), strings.TrimSpace(channel.Category), tags, strings.TrimSpace(channel.LiveState), current, created, updated)
+		// Snapshots written before stream keys were hashed still carry the
+		// plaintext key; hash it on the way in so it never reaches Postgres.
+		streamKeyHash, streamKeyHintValue := strings.TrimSpace(channel.StreamKeyHash), channel.StreamKeyHint
+		if plaintext := strings.TrimSpace(channel.StreamKey); plaintext != "" && streamKeyHash == "" {
+			streamKeyHash = HashStreamKey(plaintext)
+			streamKeyHintValue = streamKeyHint(plaintext)
+		}
+		if streamKeyHash == "" {
+			if err := im.reject("channels", id, fmt.Errorf("channel %s has no stream key", id)); err != nil {
+				return err
+			}
+			continue
+		}
+		recordingPolicy, err := normalizeRecordingPolicy(channel.RecordingPolicy)
+		if err != nil {
+			if err := im.reject("channels", id, fmt.Errorf("channel %s: %w", id, err)); err != nil {
+				return err
+			}
+			continue
+		}
+		language, err := normalizeChannelLanguage(channel.Language)
+		if err != nil {
+			if err := im.reject("channels", id, fmt.Errorf("channel %s: %w", id, err)); err != nil {
+				return err
+			}
+			continue
+		}
+		contentWarnings, err := normalizeContentWarnings(channel.ContentWarnings)
+		if err != nil {
+			if err := im.reject("channels", id, fmt.Errorf("channel %s: %w", id, err)); err != nil {
+				return err
+			}
+			continue
+		}
+		limitsPayload, err := encodeTranscodeLimits(channel.TranscodeLimits)
+		if err != nil {
+			if err := im.reject("channels", id, fmt.Errorf("channel %s: %w", id, err)); err != nil {
+				return err
+			}
+			continue
+		}
+		_, err = im.exec(ctx, "channels", id, "INSERT INTO channels (id, owner_id, stream_key_hash, stream_key_hint, title, category, tags, live_state, current_session_id, created_at, updated_at, playback_restriction, playback_previews, recording_policy, mature_content, transcode_limits, starting_since, chat_max_caps_percent, chat_restrict_links, chat_subscribers_only, chat_welcome_message, language, content_warnings, slug, slug_changed_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19, $20, $21, $22, $23, $24, $25) ON CONFLICT (id) DO NOTHING", id, strings.TrimSpace(channel.OwnerID), streamKeyHash, streamKeyHintValue, strings.TrimSpace(channel.Title), strings.TrimSpace(channel.Category), tags, strings.TrimSpace(channel.LiveState), current, created, updated, strings.TrimSpace(channel.PlaybackRestriction), channel.PlaybackPreviews, recordingPolicy, channel.MatureContent, limitsPayload, channel.StartingSince, channel.ChatMaxCapsPercent, channel.ChatRestrictLinks, channel.ChatSubscribersOnly, channel.ChatWelcomeMessage, language, nonNilStrings(contentWarnings), channelSlugKey(channel.Slug), channel.SlugChangedAt)
 		if err != nil {
 			return fmt.Errorf("insert channel %s: %w", id, err)
 		}
@@ -198,10 +458,10 @@ func (r *postgresRepository) importSnapshotChannels(ctx context.Context, tx pgx.
 	return nil
 }
 
-func (r *postgresRepository) importSnapshotFollows(ctx context.Context, tx pgx.Tx, follows map[string]map[string]time.Time) error {
+func (r *postgresRepository) importSnapshotFollows(ctx context.Context, im *snapshotImporter, follows map[string]map[string]time.Time) error {
 	for userID, entries := range follows {
 		for channelID, followedAt := range entries {
-			_, err := tx.Exec(ctx, "INSERT INTO follows (user_id, channel_id, followed_at) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING", strings.TrimSpace(userID), strings.TrimSpace(channelID), followedAt.UTC())
+			_, err := im.exec(ctx, "follows", userID+"/"+channelID, "INSERT INTO follows (user_id, channel_id, followed_at) VALUES ($1, $2, $3) ON CONFLICT DO NOTHING", strings.TrimSpace(userID), strings.TrimSpace(channelID), followedAt.UTC())
 			if err != nil {
 				return fmt.Errorf("insert follow %s->%s: %w", userID, channelID, err)
 			}
@@ -210,7 +470,47 @@ func (r *postgresRepository) importSnapshotFollows(ctx context.Context, tx pgx.T
 	return nil
 }
 
-func (r *postgresRepository) importSnapshotStreamSessions(ctx context.Context, tx pgx.Tx, sessions map[string]models.StreamSession) error {
+func (r *postgresRepository) importSnapshotChannelEditors(ctx context.Context, im *snapshotImporter, editors map[string]map[string]models.ChannelEditor) error {
+	for channelID, entries := range editors {
+		for userID, editor := range entries {
+			var grantor *string
+			if trimmed := strings.TrimSpace(editor.GrantedBy); trimmed != "" {
+				grantor = &trimmed
+			}
+			grantedAt := editor.GrantedAt
+			if grantedAt.IsZero() {
+				grantedAt = time.Now()
+			}
+			_, err := im.exec(ctx, "channel_editors", channelID+"/"+userID, "INSERT INTO channel_editors (channel_id, user_id, granted_by, granted_at, expires_at) VALUES ($1, $2, $3, $4, $5) ON CONFLICT DO NOTHING", strings.TrimSpace(channelID), strings.TrimSpace(userID), grantor, grantedAt.UTC(), nullableGrantExpiry(editor.ExpiresAt))
+			if err != nil {
+				return fmt.Errorf("insert channel editor %s->%s: %w", userID, channelID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *postgresRepository) importSnapshotChannelModerators(ctx context.Context, im *snapshotImporter, moderators map[string]map[string]models.ChannelModerator) error {
+	for channelID, entries := range moderators {
+		for userID, moderator := range entries {
+			var grantor *string
+			if trimmed := strings.TrimSpace(moderator.GrantedBy); trimmed != "" {
+				grantor = &trimmed
+			}
+			grantedAt := moderator.GrantedAt
+			if grantedAt.IsZero() {
+				grantedAt = time.Now()
+			}
+			_, err := im.exec(ctx, "channel_moderators", channelID+"/"+userID, "INSERT INTO channel_moderators (channel_id, user_id, granted_by, granted_at, expires_at) VALUES ($1, $2, $3, $4, $5) ON CONFLICT DO NOTHING", strings.TrimSpace(channelID), strings.TrimSpace(userID), grantor, grantedAt.UTC(), nullableGrantExpiry(moderator.ExpiresAt))
+			if err != nil {
+				return fmt.Errorf("insert channel moderator %s->%s: %w", userID, channelID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *postgresRepository) importSnapshotStreamSessions(ctx context.Context, im *snapshotImporter, sessions map[string]models.StreamSession) error {
 	if len(sessions) == 0 {
 		return nil
 	}
@@ -247,12 +547,29 @@ func (r *postgresRepository) importSnapshotStreamSessions(ctx context.Context, t
 		if ingestJobIDs == nil {
 			ingestJobIDs = []string{}
 		}
-		_, err := tx.Exec(ctx, "INSERT INTO stream_sessions (id, channel_id, started_at, ended_at, renditions, peak_concurrent, origin_url, playback_url, ingest_endpoints, ingest_job_ids) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) ON CONFLICT (id) DO NOTHING", id, strings.TrimSpace(session.ChannelID), started, ended, renditions, session.PeakConcurrent, strings.TrimSpace(session.OriginURL), strings.TrimSpace(session.PlaybackURL), ingestEndpoints, ingestJobIDs)
+		settings, err := encodeStreamSettings(session.Settings)
+		if err != nil {
+			if err := im.reject("stream_sessions", id, fmt.Errorf("stream session %s: %w", id, err)); err != nil {
+				return err
+			}
+			continue
+		}
+		playbackEndpoints, err := encodePlaybackEndpoints(session.PlaybackEndpoints)
+		if err != nil {
+			if err := im.reject("stream_sessions", id, fmt.Errorf("stream session %s: %w", id, err)); err != nil {
+				return err
+			}
+			continue
+		}
+		written, err := im.exec(ctx, "stream_sessions", id, "INSERT INTO stream_sessions (id, channel_id, started_at, ended_at, renditions, peak_concurrent, origin_url, playback_url, ingest_endpoints, ingest_job_ids, settings, preview_url, receiving_media, media_confirmed_at, interrupted_at, playback_endpoints, publish_source) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17) ON CONFLICT (id) DO NOTHING", id, strings.TrimSpace(session.ChannelID), started, ended, renditions, session.PeakConcurrent, strings.TrimSpace(session.OriginURL), strings.TrimSpace(session.PlaybackURL), ingestEndpoints, ingestJobIDs, settings, strings.TrimSpace(session.PreviewURL), session.ReceivingMedia, session.MediaConfirmedAt, session.InterruptedAt, playbackEndpoints, strings.TrimSpace(session.PublishSource))
 		if err != nil {
 			return fmt.Errorf("insert stream session %s: %w", id, err)
 		}
+		if !written {
+			continue
+		}
 		for _, manifest := range session.RenditionManifests {
-			_, err := tx.Exec(ctx, "INSERT INTO stream_session_manifests (session_id, name, manifest_url, bitrate) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING", id, strings.TrimSpace(manifest.Name), strings.TrimSpace(manifest.ManifestURL), manifest.Bitrate)
+			_, err := im.exec(ctx, "stream_session_manifests", id+"/"+manifest.Name, "INSERT INTO stream_session_manifests (session_id, name, manifest_url, bitrate) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING", id, strings.TrimSpace(manifest.Name), strings.TrimSpace(manifest.ManifestURL), manifest.Bitrate)
 			if err != nil {
 				return fmt.Errorf("insert stream manifest %s/%s: %w", id, manifest.Name, err)
 			}
@@ -261,7 +578,7 @@ func (r *postgresRepository) importSnapshotStreamSessions(ctx context.Context, t
 	return nil
 }
 
-func (r *postgresRepository) importSnapshotRecordings(ctx context.Context, tx pgx.Tx, recordings map[string]models.Recording) error {
+func (r *postgresRepository) importSnapshotRecordings(ctx context.Context, im *snapshotImporter, recordings map[string]models.Recording) error {
 	if len(recordings) == 0 {
 		return nil
 	}
@@ -284,17 +601,67 @@ func (r *postgresRepository) importSnapshotRecordings(ctx context.Context, tx pg
 			ts := recording.RetainUntil.UTC()
 			recording.RetainUntil = &ts
 		}
-		for i := range recording.Thumbnails {
-			recording.Thumbnails[i].CreatedAt = recording.Thumbnails[i].CreatedAt.UTC()
-		}
-		if err := r.insertRecording(ctx, tx, recording); err != nil {
+		if err := r.importSnapshotRecording(ctx, im, recording); err != nil {
 			return err
 		}
 	}
 	return nil
 }
 
-func (r *postgresRepository) importSnapshotUploads(ctx context.Context, tx pgx.Tx, uploads map[string]models.Upload) error {
+// importSnapshotRecording writes a recording with its renditions and
+// thumbnails. Unlike insertRecording it skips rows that already exist, so a
+// resumed import can run over recordings an earlier run wrote.
+func (r *postgresRepository) importSnapshotRecording(ctx context.Context, im *snapshotImporter, recording models.Recording) error {
+	metadata := recording.Metadata
+	if metadata == nil {
+		metadata = make(map[string]string)
+	}
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return im.reject("recordings", recording.ID, fmt.Errorf("encode recording metadata %s: %w", recording.ID, err))
+	}
+	chaptersJSON, err := encodeRecordingChapters(recording.Chapters)
+	if err != nil {
+		return im.reject("recordings", recording.ID, fmt.Errorf("encode recording chapters %s: %w", recording.ID, err))
+	}
+	written, err := im.exec(ctx, "recordings", recording.ID, "INSERT INTO recordings (id, channel_id, session_id, title, description, tags, duration_seconds, playback_base_url, metadata, published_at, created_at, retain_until, size_bytes, over_quota, chapters, premiere_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16) ON CONFLICT (id) DO NOTHING",
+		recording.ID,
+		recording.ChannelID,
+		recording.SessionID,
+		recording.Title,
+		recording.Description,
+		normalizeTags(recording.Tags),
+		recording.DurationSeconds,
+		recording.PlaybackBaseURL,
+		metadataJSON,
+		recording.PublishedAt,
+		recording.CreatedAt,
+		recording.RetainUntil,
+		recording.SizeBytes,
+		recording.OverQuota,
+		chaptersJSON,
+		recording.PremiereAt,
+	)
+	if err != nil {
+		return fmt.Errorf("insert recording %s: %w", recording.ID, err)
+	}
+	if !written {
+		return nil
+	}
+	for _, rendition := range recording.Renditions {
+		if _, err := im.exec(ctx, "recording_renditions", recording.ID+"/"+rendition.Name, "INSERT INTO recording_renditions (recording_id, name, manifest_url, bitrate) VALUES ($1, $2, $3, $4) ON CONFLICT DO NOTHING", recording.ID, rendition.Name, rendition.ManifestURL, rendition.Bitrate); err != nil {
+			return fmt.Errorf("insert recording rendition %s: %w", rendition.Name, err)
+		}
+	}
+	for _, thumb := range recording.Thumbnails {
+		if _, err := im.exec(ctx, "recording_thumbnails", thumb.ID, "INSERT INTO recording_thumbnails (id, recording_id, url, width, height, created_at) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (id) DO NOTHING", thumb.ID, recording.ID, thumb.URL, thumb.Width, thumb.Height, thumb.CreatedAt.UTC()); err != nil {
+			return fmt.Errorf("insert recording thumbnail %s: %w", thumb.ID, err)
+		}
+	}
+	return nil
+}
+
+func (r *postgresRepository) importSnapshotUploads(ctx context.Context, im *snapshotImporter, uploads map[string]models.Upload) error {
 	if len(uploads) == 0 {
 		return nil
 	}
@@ -315,7 +682,10 @@ func (r *postgresRepository) importSnapshotUploads(ctx context.Context, tx pgx.T
 		}
 		metadataJSON, err := json.Marshal(metadata)
 		if err != nil {
-			return fmt.Errorf("encode upload metadata %s: %w", id, err)
+			if err := im.reject("uploads", id, fmt.Errorf("encode upload metadata %s: %w", id, err)); err != nil {
+				return err
+			}
+			continue
 		}
 		created := upload.CreatedAt.UTC()
 		if created.IsZero() {
@@ -337,7 +707,18 @@ func (r *postgresRepository) importSnapshotUploads(ctx context.Context, tx pgx.T
 		if strings.TrimSpace(upload.Error) != "" {
 			errorText = strings.TrimSpace(upload.Error)
 		}
-		_, err = tx.Exec(ctx, "INSERT INTO uploads (id, channel_id, title, filename, size_bytes, status, progress, recording_id, playback_url, metadata, error, created_at, updated_at, completed_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14) ON CONFLICT (id) DO NOTHING", id, strings.TrimSpace(upload.ChannelID), strings.TrimSpace(upload.Title), strings.TrimSpace(upload.Filename), upload.SizeBytes, strings.TrimSpace(upload.Status), upload.Progress, recordingID, strings.TrimSpace(upload.PlaybackURL), metadataJSON, errorText, created, updated, completedAt)
+		var contentHash any
+		if strings.TrimSpace(upload.ContentHash) != "" {
+			contentHash = strings.ToLower(strings.TrimSpace(upload.ContentHash))
+		}
+		stepsJSON, err := encodeUploadSteps(upload.Steps)
+		if err != nil {
+			if err := im.reject("uploads", id, fmt.Errorf("encode upload steps %s: %w", id, err)); err != nil {
+				return err
+			}
+			continue
+		}
+		_, err = im.exec(ctx, "uploads", id, "INSERT INTO uploads (id, channel_id, title, filename, size_bytes, content_hash, status, progress, recording_id, playback_url, metadata, error, created_at, updated_at, completed_at, output_bytes, steps) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17) ON CONFLICT (id) DO NOTHING", id, strings.TrimSpace(upload.ChannelID), strings.TrimSpace(upload.Title), strings.TrimSpace(upload.Filename), upload.SizeBytes, contentHash, strings.TrimSpace(upload.Status), upload.Progress, recordingID, strings.TrimSpace(upload.PlaybackURL), metadataJSON, errorText, created, updated, completedAt, upload.OutputBytes, stepsJSON)
 		if err != nil {
 			return fmt.Errorf("insert upload %s: %w", id, err)
 		}
@@ -345,7 +726,7 @@ func (r *postgresRepository) importSnapshotUploads(ctx context.Context, tx pgx.T
 	return nil
 }
 
-func (r *postgresRepository) importSnapshotClipExports(ctx context.Context, tx pgx.Tx, clips map[string]models.ClipExport) error {
+func (r *postgresRepository) importSnapshotClipExports(ctx context.Context, im *snapshotImporter, clips map[string]models.ClipExport) error {
 	if len(clips) == 0 {
 		return nil
 	}
@@ -372,7 +753,17 @@ func (r *postgresRepository) importSnapshotClipExports(ctx context.Context, tx p
 		if strings.TrimSpace(clip.StorageObject) != "" {
 			storageObject = strings.TrimSpace(clip.StorageObject)
 		}
-		_, err := tx.Exec(ctx, "INSERT INTO clip_exports (id, recording_id, channel_id, session_id, title, start_seconds, end_seconds, status, playback_url, created_at, completed_at, storage_object) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) ON CONFLICT (id) DO NOTHING", id, strings.TrimSpace(clip.RecordingID), strings.TrimSpace(clip.ChannelID), strings.TrimSpace(clip.SessionID), strings.TrimSpace(clip.Title), clip.StartSeconds, clip.EndSeconds, strings.TrimSpace(clip.Status), strings.TrimSpace(clip.PlaybackURL), created, completed, storageObject)
+		// Live clips that were never linked to a recording keep a NULL
+		// recording_id, and clips from deleted viewers a NULL created_by.
+		var recordingID any
+		if strings.TrimSpace(clip.RecordingID) != "" {
+			recordingID = strings.TrimSpace(clip.RecordingID)
+		}
+		var createdBy any
+		if strings.TrimSpace(clip.CreatedBy) != "" {
+			createdBy = strings.TrimSpace(clip.CreatedBy)
+		}
+		_, err := im.exec(ctx, "clip_exports", id, "INSERT INTO clip_exports (id, recording_id, channel_id, session_id, title, start_seconds, end_seconds, status, playback_url, created_at, completed_at, storage_object, created_by) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13) ON CONFLICT (id) DO NOTHING", id, recordingID, strings.TrimSpace(clip.ChannelID), strings.TrimSpace(clip.SessionID), strings.TrimSpace(clip.Title), clip.StartSeconds, clip.EndSeconds, strings.TrimSpace(clip.Status), strings.TrimSpace(clip.PlaybackURL), created, completed, storageObject, createdBy)
 		if err != nil {
 			return fmt.Errorf("insert clip export %s: %w", id, err)
 		}
@@ -380,7 +771,7 @@ func (r *postgresRepository) importSnapshotClipExports(ctx context.Context, tx p
 	return nil
 }
 
-func (r *postgresRepository) importSnapshotChatMessages(ctx context.Context, tx pgx.Tx, messages map[string]models.ChatMessage) error {
+func (r *postgresRepository) importSnapshotChatMessages(ctx context.Context, im *snapshotImporter, messages map[string]models.ChatMessage) error {
 	if len(messages) == 0 {
 		return nil
 	}
@@ -389,6 +780,19 @@ func (r *postgresRepository) importSnapshotChatMessages(ctx context.Context, tx
 		ids = append(ids, id)
 	}
 	sort.Strings(ids)
+	// Client message IDs may have been reused once their dedupe window
+	// passed; only the newest message keeps one so the unique index holds.
+	latestClientIDs := make(map[string]string)
+	for _, key := range ids {
+		msg := messages[key]
+		if msg.ClientMessageID == "" {
+			continue
+		}
+		clientKey := chat.ClientMessageKey(strings.TrimSpace(msg.ChannelID), strings.TrimSpace(msg.UserID), msg.ClientMessageID)
+		if latest, ok := latestClientIDs[clientKey]; !ok || messages[latest].CreatedAt.Before(msg.CreatedAt) {
+			latestClientIDs[clientKey] = key
+		}
+	}
 	for _, key := range ids {
 		msg := messages[key]
 		id := strings.TrimSpace(msg.ID)
@@ -399,7 +803,11 @@ func (r *postgresRepository) importSnapshotChatMessages(ctx context.Context, tx
 		if created.IsZero() {
 			created = time.Now().UTC()
 		}
-		_, err := tx.Exec(ctx, "INSERT INTO chat_messages (id, channel_id, user_id, content, created_at) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (id) DO NOTHING", id, strings.TrimSpace(msg.ChannelID), strings.TrimSpace(msg.UserID), msg.Content, created)
+		var clientParam any
+		if msg.ClientMessageID != "" && latestClientIDs[chat.ClientMessageKey(strings.TrimSpace(msg.ChannelID), strings.TrimSpace(msg.UserID), msg.ClientMessageID)] == key {
+			clientParam = msg.ClientMessageID
+		}
+		_, err := im.exec(ctx, "chat_messages", id, "INSERT INTO chat_messages (id, channel_id, user_id, content, client_message_id, seq, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (id) DO NOTHING", id, strings.TrimSpace(msg.ChannelID), strings.TrimSpace(msg.UserID), msg.Content, clientParam, msg.Seq, created)
 		if err != nil {
 			return fmt.Errorf("insert chat message %s: %w", id, err)
 		}
@@ -407,7 +815,7 @@ func (r *postgresRepository) importSnapshotChatMessages(ctx context.Context, tx
 	return nil
 }
 
-func (r *postgresRepository) importSnapshotChatModeration(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+func (r *postgresRepository) importSnapshotChatModeration(ctx context.Context, im *snapshotImporter, snapshot *Snapshot) error {
 	for channelID, entries := range snapshot.ChatBans {
 		for userID, issuedAt := range entries {
 			actor := lookupString(snapshot.ChatBanActors, channelID, userID)
@@ -416,7 +824,7 @@ func (r *postgresRepository) importSnapshotChatModeration(ctx context.Context, t
 				actorParam = actor
 			}
 			reason := lookupString(snapshot.ChatBanReasons, channelID, userID)
-			_, err := tx.Exec(ctx, "INSERT INTO chat_bans (channel_id, user_id, actor_id, reason, issued_at) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (channel_id, user_id) DO NOTHING", strings.TrimSpace(channelID), strings.TrimSpace(userID), actorParam, reason, issuedAt.UTC())
+			_, err := im.exec(ctx, "chat_bans", channelID+"/"+userID, "INSERT INTO chat_bans (channel_id, user_id, actor_id, reason, issued_at) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (channel_id, user_id) DO NOTHING", strings.TrimSpace(channelID), strings.TrimSpace(userID), actorParam, reason, issuedAt.UTC())
 			if err != nil {
 				return fmt.Errorf("insert chat ban %s/%s: %w", channelID, userID, err)
 			}
@@ -434,7 +842,7 @@ func (r *postgresRepository) importSnapshotChatModeration(ctx context.Context, t
 			if issuedAt.IsZero() {
 				issuedAt = expiresAt
 			}
-			_, err := tx.Exec(ctx, "INSERT INTO chat_timeouts (channel_id, user_id, actor_id, reason, issued_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (channel_id, user_id) DO NOTHING", strings.TrimSpace(channelID), strings.TrimSpace(userID), actorParam, reason, issuedAt.UTC(), expiresAt.UTC())
+			_, err := im.exec(ctx, "chat_timeouts", channelID+"/"+userID, "INSERT INTO chat_timeouts (channel_id, user_id, actor_id, reason, issued_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (channel_id, user_id) DO NOTHING", strings.TrimSpace(channelID), strings.TrimSpace(userID), actorParam, reason, issuedAt.UTC(), expiresAt.UTC())
 			if err != nil {
 				return fmt.Errorf("insert chat timeout %s/%s: %w", channelID, userID, err)
 			}
@@ -443,7 +851,7 @@ func (r *postgresRepository) importSnapshotChatModeration(ctx context.Context, t
 	return nil
 }
 
-func (r *postgresRepository) importSnapshotChatReports(ctx context.Context, tx pgx.Tx, reports map[string]models.ChatReport) error {
+func (r *postgresRepository) importSnapshotChatReports(ctx context.Context, im *snapshotImporter, reports map[string]models.ChatReport) error {
 	if len(reports) == 0 {
 		return nil
 	}
@@ -453,7 +861,7 @@ func (r *postgresRepository) importSnapshotChatReports(ctx context.Context, tx p
 	}
 	sort.Strings(ids)
 	for _, key := range ids {
-		report := reports[key]
+		report := withReportDefaults(reports[key])
 		id := strings.TrimSpace(report.ID)
 		if id == "" {
 			id = key
@@ -478,7 +886,11 @@ func (r *postgresRepository) importSnapshotChatReports(ctx context.Context, tx p
 		if strings.TrimSpace(report.EvidenceURL) != "" {
 			evidence = strings.TrimSpace(report.EvidenceURL)
 		}
-		_, err := tx.Exec(ctx, "INSERT INTO chat_reports (id, channel_id, reporter_id, target_id, reason, message_id, evidence_url, status, resolution, resolver_id, created_at, resolved_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) ON CONFLICT (id) DO NOTHING", id, strings.TrimSpace(report.ChannelID), strings.TrimSpace(report.ReporterID), strings.TrimSpace(report.TargetID), strings.TrimSpace(report.Reason), messageID, evidence, strings.TrimSpace(report.Status), strings.TrimSpace(report.Resolution), resolver, created, resolvedAt)
+		var claimant models.ReportClaimant
+		if report.Claimant != nil {
+			claimant = *report.Claimant
+		}
+		_, err := im.exec(ctx, "chat_reports", id, "INSERT INTO chat_reports (id, channel_id, reporter_id, target_id, subject_type, subject_id, category, reason, message_id, evidence_url, claimant_name, claimant_email, claimant_phone, claimant_address, status, resolution, resolver_id, created_at, resolved_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18, $19) ON CONFLICT (id) DO NOTHING", id, strings.TrimSpace(report.ChannelID), strings.TrimSpace(report.ReporterID), strings.TrimSpace(report.TargetID), report.SubjectType, reportText(strings.TrimSpace(report.SubjectID)), report.Category, strings.TrimSpace(report.Reason), messageID, evidence, reportText(claimant.Name), reportText(claimant.Email), reportText(claimant.Phone), reportText(claimant.Address), strings.TrimSpace(report.Status), strings.TrimSpace(report.Resolution), resolver, created, resolvedAt)
 		if err != nil {
 			return fmt.Errorf("insert chat report %s: %w", id, err)
 		}
@@ -486,7 +898,7 @@ func (r *postgresRepository) importSnapshotChatReports(ctx context.Context, tx p
 	return nil
 }
 
-func (r *postgresRepository) importSnapshotTips(ctx context.Context, tx pgx.Tx, tips map[string]models.Tip) error {
+func (r *postgresRepository) importSnapshotTips(ctx context.Context, im *snapshotImporter, tips map[string]models.Tip) error {
 	if len(tips) == 0 {
 		return nil
 	}
@@ -513,7 +925,7 @@ func (r *postgresRepository) importSnapshotTips(ctx context.Context, tx pgx.Tx,
 		if strings.TrimSpace(tip.Message) != "" {
 			message = strings.TrimSpace(tip.Message)
 		}
-		_, err := tx.Exec(ctx, "INSERT INTO tips (id, channel_id, from_user_id, amount, currency, provider, reference, wallet_address, message, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) ON CONFLICT (id) DO NOTHING", id, strings.TrimSpace(tip.ChannelID), strings.TrimSpace(tip.FromUserID), tip.Amount.DecimalString(), strings.TrimSpace(tip.Currency), strings.TrimSpace(tip.Provider), strings.TrimSpace(tip.Reference), wallet, message, created)
+		_, err := im.exec(ctx, "tips", id, "INSERT INTO tips (id, channel_id, from_user_id, amount, currency, provider, reference, wallet_address, message, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) ON CONFLICT (id) DO NOTHING", id, strings.TrimSpace(tip.ChannelID), strings.TrimSpace(tip.FromUserID), tip.Amount.DecimalString(), strings.TrimSpace(tip.Currency), strings.TrimSpace(tip.Provider), strings.TrimSpace(tip.Reference), wallet, message, created)
 		if err != nil {
 			return fmt.Errorf("insert tip %s: %w", id, err)
 		}
@@ -521,7 +933,7 @@ func (r *postgresRepository) importSnapshotTips(ctx context.Context, tx pgx.Tx,
 	return nil
 }
 
-func (r *postgresRepository) importSnapshotSubscriptions(ctx context.Context, tx pgx.Tx, subs map[string]models.Subscription) error {
+func (r *postgresRepository) importSnapshotSubscriptions(ctx context.Context, im *snapshotImporter, subs map[string]models.Subscription) error {
 	if len(subs) == 0 {
 		return nil
 	}
@@ -560,7 +972,11 @@ func (r *postgresRepository) importSnapshotSubscriptions(ctx context.Context, tx
 		if strings.TrimSpace(sub.ExternalReference) != "" {
 			externalRef = strings.TrimSpace(sub.ExternalReference)
 		}
-		_, err := tx.Exec(ctx, "INSERT INTO subscriptions (id, channel_id, user_id, tier, provider, reference, amount, currency, started_at, expires_at, auto_renew, status, cancelled_by, cancelled_reason, cancelled_at, external_reference) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16) ON CONFLICT (id) DO NOTHING", id, strings.TrimSpace(sub.ChannelID), strings.TrimSpace(sub.UserID), strings.TrimSpace(sub.Tier), strings.TrimSpace(sub.Provider), strings.TrimSpace(sub.Reference), sub.Amount.DecimalString(), strings.TrimSpace(sub.Currency), started, expires, sub.AutoRenew, strings.TrimSpace(sub.Status), cancelledBy, cancelledReason, cancelledAt, externalRef)
+		var gifterID any
+		if strings.TrimSpace(sub.GifterID) != "" {
+			gifterID = strings.TrimSpace(sub.GifterID)
+		}
+		_, err := im.exec(ctx, "subscriptions", id, "INSERT INTO subscriptions (id, channel_id, user_id, tier, provider, reference, amount, currency, started_at, expires_at, auto_renew, status, cancelled_by, cancelled_reason, cancelled_at, external_reference, gifter_id, is_gift) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16, $17, $18) ON CONFLICT (id) DO NOTHING", id, strings.TrimSpace(sub.ChannelID), strings.TrimSpace(sub.UserID), strings.TrimSpace(sub.Tier), strings.TrimSpace(sub.Provider), strings.TrimSpace(sub.Reference), sub.Amount.DecimalString(), strings.TrimSpace(sub.Currency), started, expires, sub.AutoRenew, strings.TrimSpace(sub.Status), cancelledBy, cancelledReason, cancelledAt, externalRef, gifterID, sub.IsGift)
 		if err != nil {
 			return fmt.Errorf("insert subscription %s: %w", id, err)
 		}
@@ -568,7 +984,7 @@ func (r *postgresRepository) importSnapshotSubscriptions(ctx context.Context, tx
 	return nil
 }
 
-func (r *postgresRepository) importSnapshotOAuthAccounts(ctx context.Context, tx pgx.Tx, accounts map[string]models.OAuthAccount) error {
+func (r *postgresRepository) importSnapshotOAuthAccounts(ctx context.Context, im *snapshotImporter, accounts map[string]models.OAuthAccount) error {
 	if len(accounts) == 0 {
 		return nil
 	}
@@ -583,7 +999,7 @@ func (r *postgresRepository) importSnapshotOAuthAccounts(ctx context.Context, tx
 		if linked.IsZero() {
 			linked = time.Now().UTC()
 		}
-		_, err := tx.Exec(ctx, "INSERT INTO oauth_accounts (provider, subject, user_id, email, display_name, linked_at) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (provider, subject) DO NOTHING", strings.TrimSpace(account.Provider), strings.TrimSpace(account.Subject), strings.TrimSpace(account.UserID), strings.TrimSpace(account.Email), strings.TrimSpace(account.DisplayName), linked)
+		_, err := im.exec(ctx, "oauth_accounts", key, "INSERT INTO oauth_accounts (provider, subject, subject_index, user_id, email, display_name, linked_at) VALUES ($1, $2, NULLIF($3, ''), $4, $5, $6, $7) ON CONFLICT DO NOTHING", strings.TrimSpace(account.Provider), strings.TrimSpace(account.Subject), strings.TrimSpace(account.SubjectIndex), strings.TrimSpace(account.UserID), strings.TrimSpace(account.Email), strings.TrimSpace(account.DisplayName), linked)
 		if err != nil {
 			return fmt.Errorf("insert oauth account %s: %w", key, err)
 		}
@@ -591,6 +1007,413 @@ func (r *postgresRepository) importSnapshotOAuthAccounts(ctx context.Context, tx
 	return nil
 }
 
+func (r *postgresRepository) importSnapshotAPITokens(ctx context.Context, im *snapshotImporter, tokens map[string]models.APIToken) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(tokens))
+	for id := range tokens {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		token := tokens[id]
+		created := token.CreatedAt.UTC()
+		if created.IsZero() {
+			created = time.Now().UTC()
+		}
+		var expiresAt any
+		if token.ExpiresAt != nil {
+			expiresAt = token.ExpiresAt.UTC()
+		}
+		scopes := append([]string{}, token.Scopes...)
+		_, err := im.exec(ctx, "api_tokens", id, "INSERT INTO api_tokens (id, user_id, name, scopes, token_hash, created_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (id) DO NOTHING", id, token.UserID, token.Name, scopes, token.TokenHash, created, expiresAt)
+		if err != nil {
+			return fmt.Errorf("insert api token %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+func (r *postgresRepository) importSnapshotNotificationPreferences(ctx context.Context, im *snapshotImporter, preferences map[string]models.NotificationPreferences) error {
+	if len(preferences) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(preferences))
+	for id := range preferences {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, userID := range ids {
+		prefs := preferences[userID]
+		updated := time.Now().UTC()
+		if prefs.UpdatedAt != nil {
+			updated = prefs.UpdatedAt.UTC()
+		}
+		_, err := im.exec(ctx, "notification_preferences", userID, "INSERT INTO notification_preferences ("+notificationPreferenceColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) ON CONFLICT (user_id) DO NOTHING",
+			userID,
+			prefs.FollowedLive.InApp, prefs.FollowedLive.Email,
+			prefs.Mentions.InApp, prefs.Mentions.Email,
+			prefs.Moderation.InApp, prefs.Moderation.Email,
+			prefs.Account.InApp, prefs.Account.Email,
+			updated,
+		)
+		if err != nil {
+			return fmt.Errorf("insert notification preferences for %s: %w", userID, err)
+		}
+	}
+	return nil
+}
+
+func (r *postgresRepository) importSnapshotPlaybackPreferences(ctx context.Context, im *snapshotImporter, preferences map[string]models.PlaybackPreferences) error {
+	if len(preferences) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(preferences))
+	for id := range preferences {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, userID := range ids {
+		prefs := preferences[userID]
+		quality, ok := models.NormalizePlaybackQuality(prefs.DefaultQuality)
+		if !ok {
+			quality = models.PlaybackQualityAuto
+		}
+		updated := time.Now().UTC()
+		if prefs.UpdatedAt != nil {
+			updated = prefs.UpdatedAt.UTC()
+		}
+		_, err := im.exec(ctx, "playback_preferences", userID, "INSERT INTO playback_preferences ("+playbackPreferenceColumns+") VALUES ($1, $2, $3, $4, $5) ON CONFLICT (user_id) DO NOTHING",
+			userID, quality, prefs.PreferLowLatency, prefs.MutedAutoplay, updated,
+		)
+		if err != nil {
+			return fmt.Errorf("insert playback preferences for %s: %w", userID, err)
+		}
+	}
+	return nil
+}
+
+// importSnapshotBadges copies badge definitions and grants. Definitions
+// replace the defaults the schema migration seeds, so relabelled badges keep
+// their labels.
+func (r *postgresRepository) importSnapshotBadges(ctx context.Context, im *snapshotImporter, definitions map[string]models.BadgeDefinition, grants map[string]map[string]models.BadgeGrant) error {
+	slugs := make([]string, 0, len(definitions))
+	for slug := range definitions {
+		slugs = append(slugs, slug)
+	}
+	sort.Strings(slugs)
+	for _, slug := range slugs {
+		definition := definitions[slug]
+		_, err := im.upsert(ctx, "badge_definitions", slug, "INSERT INTO badge_definitions ("+badgeDefinitionColumns+") VALUES ($1, $2, $3, $4, $5) "+
+			"ON CONFLICT (slug) DO UPDATE SET label = EXCLUDED.label, icon_url = EXCLUDED.icon_url, created_at = EXCLUDED.created_at, updated_at = EXCLUDED.updated_at "+
+			"RETURNING (xmax = 0)",
+			slug, definition.Label, definition.IconURL, definition.CreatedAt, definition.UpdatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("insert badge definition %s: %w", slug, err)
+		}
+	}
+	userIDs := make([]string, 0, len(grants))
+	for userID := range grants {
+		userIDs = append(userIDs, userID)
+	}
+	sort.Strings(userIDs)
+	for _, userID := range userIDs {
+		held := make([]models.BadgeGrant, 0, len(grants[userID]))
+		for _, grant := range grants[userID] {
+			held = append(held, grant)
+		}
+		sortBadgeGrants(held)
+		for _, grant := range held {
+			_, err := im.exec(ctx, "user_badges", userID+"/"+grant.Slug, "INSERT INTO user_badges ("+badgeGrantColumns+") VALUES ($1, $2, $3, $4) ON CONFLICT (user_id, badge_slug) DO NOTHING",
+				userID, grant.Slug, grant.GrantedBy, grant.GrantedAt,
+			)
+			if err != nil {
+				return fmt.Errorf("insert badge grant %s for %s: %w", grant.Slug, userID, err)
+			}
+		}
+	}
+	return nil
+}
+
+func (r *postgresRepository) importSnapshotStreamMarkers(ctx context.Context, im *snapshotImporter, markers map[string]models.StreamMarker) error {
+	if len(markers) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(markers))
+	for id := range markers {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		marker := markers[id]
+		var createdBy any
+		if marker.CreatedBy != "" {
+			createdBy = marker.CreatedBy
+		}
+		_, err := im.exec(ctx, "stream_markers", id, "INSERT INTO stream_markers ("+streamMarkerColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (id) DO NOTHING",
+			marker.ID,
+			marker.ChannelID,
+			marker.SessionID,
+			marker.Label,
+			marker.OffsetSeconds,
+			createdBy,
+			marker.CreatedAt.UTC(),
+		)
+		if err != nil {
+			return fmt.Errorf("insert stream marker %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+// importSnapshotRestreamTargets copies targets as stored. Stream keys stay
+// sealed, so the target server must use the same secret key to restream.
+func (r *postgresRepository) importSnapshotRestreamTargets(ctx context.Context, im *snapshotImporter, targets map[string]models.RestreamTarget) error {
+	if len(targets) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(targets))
+	for id := range targets {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		target := targets[id]
+		_, err := im.exec(ctx, "restream_targets", id, "INSERT INTO restream_targets ("+restreamTargetColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8) ON CONFLICT (id) DO NOTHING",
+			target.ID,
+			target.ChannelID,
+			target.Label,
+			target.URL,
+			target.StreamKeyCiphertext,
+			target.Enabled,
+			target.CreatedAt.UTC(),
+			target.UpdatedAt.UTC(),
+		)
+		if err != nil {
+			return fmt.Errorf("insert restream target %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+func (r *postgresRepository) importSnapshotScheduleEntries(ctx context.Context, im *snapshotImporter, entries map[string]models.ScheduleEntry) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(entries))
+	for id := range entries {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		entry := entries[id]
+		var repeatUntil any
+		if entry.RepeatUntil != nil {
+			repeatUntil = entry.RepeatUntil.UTC()
+		}
+		_, err := im.exec(ctx, "schedule_entries", id, "INSERT INTO schedule_entries ("+scheduleEntryColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12) ON CONFLICT (id) DO NOTHING",
+			entry.ID,
+			entry.ChannelID,
+			entry.Title,
+			entry.Category,
+			entry.StartsAt.UTC(),
+			entry.DurationMinutes,
+			entry.TimeZone,
+			entry.Recurrence,
+			repeatUntil,
+			entry.Sequence,
+			entry.CreatedAt.UTC(),
+			entry.UpdatedAt.UTC(),
+		)
+		if err != nil {
+			return fmt.Errorf("insert schedule entry %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+func (r *postgresRepository) importSnapshotScheduleFeedTokens(ctx context.Context, im *snapshotImporter, tokens map[string]models.ScheduleFeedToken) error {
+	if len(tokens) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(tokens))
+	for id := range tokens {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, userID := range ids {
+		token := tokens[userID]
+		_, err := im.exec(ctx, "schedule_feed_tokens", userID, "INSERT INTO schedule_feed_tokens ("+scheduleFeedTokenColumns+") VALUES ($1, $2, $3) ON CONFLICT (user_id) DO NOTHING",
+			userID,
+			token.Nonce,
+			token.CreatedAt.UTC(),
+		)
+		if err != nil {
+			return fmt.Errorf("insert schedule feed token for %s: %w", userID, err)
+		}
+	}
+	return nil
+}
+
+func (r *postgresRepository) importSnapshotChannelStorage(ctx context.Context, im *snapshotImporter, entries map[string]models.ChannelStorage) error {
+	if len(entries) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(entries))
+	for id := range entries {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, channelID := range ids {
+		entry := entries[channelID]
+		var quota any
+		if entry.QuotaBytes != nil {
+			quota = *entry.QuotaBytes
+		}
+		_, err := im.exec(ctx, "channel_storage", channelID, "INSERT INTO channel_storage (channel_id, used_bytes, quota_bytes, updated_at) VALUES ($1, $2, $3, $4) ON CONFLICT (channel_id) DO NOTHING",
+			channelID,
+			entry.UsedBytes,
+			quota,
+			entry.UpdatedAt.UTC(),
+		)
+		if err != nil {
+			return fmt.Errorf("insert channel storage for %s: %w", channelID, err)
+		}
+	}
+	return nil
+}
+
+func (r *postgresRepository) importSnapshotChatPins(ctx context.Context, im *snapshotImporter, pins map[string]models.ChatPin) error {
+	if len(pins) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(pins))
+	for id := range pins {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, channelID := range ids {
+		pin := pins[channelID]
+		var messageID, authorID, pinnedBy any
+		if pin.MessageID != "" {
+			messageID = pin.MessageID
+		}
+		if pin.AuthorID != "" {
+			authorID = pin.AuthorID
+		}
+		if pin.PinnedBy != "" {
+			pinnedBy = pin.PinnedBy
+		}
+		_, err := im.exec(ctx, "chat_pins", channelID, "INSERT INTO chat_pins ("+chatPinColumns+") VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (channel_id) DO NOTHING",
+			channelID,
+			messageID,
+			authorID,
+			pin.Content,
+			pinnedBy,
+			pin.PinnedAt.UTC(),
+		)
+		if err != nil {
+			return fmt.Errorf("insert chat pin for %s: %w", channelID, err)
+		}
+	}
+	return nil
+}
+
+func (r *postgresRepository) importSnapshotChatSequences(ctx context.Context, im *snapshotImporter, sequences map[string]int64) error {
+	if len(sequences) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(sequences))
+	for id := range sequences {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, channelID := range ids {
+		_, err := im.exec(ctx, "chat_sequences", channelID, "INSERT INTO chat_sequences (channel_id, seq) VALUES ($1, $2) ON CONFLICT (channel_id) DO NOTHING", channelID, sequences[channelID])
+		if err != nil {
+			return fmt.Errorf("insert chat sequence for %s: %w", channelID, err)
+		}
+	}
+	return nil
+}
+
+func (r *postgresRepository) importSnapshotChatAppeals(ctx context.Context, im *snapshotImporter, appeals map[string]models.ChatAppeal) error {
+	if len(appeals) == 0 {
+		return nil
+	}
+	ids := make([]string, 0, len(appeals))
+	for id := range appeals {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		appeal := appeals[id]
+		var resolver, resolvedAt any
+		if appeal.ResolverID != "" {
+			resolver = appeal.ResolverID
+		}
+		if appeal.ResolvedAt != nil {
+			resolvedAt = appeal.ResolvedAt.UTC()
+		}
+		_, err := im.exec(ctx, "chat_appeals", id, "INSERT INTO chat_appeals ("+chatAppealColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) ON CONFLICT (id) DO NOTHING",
+			appeal.ID,
+			appeal.ChannelID,
+			appeal.UserID,
+			appeal.Restriction,
+			appeal.Message,
+			appeal.Status,
+			resolver,
+			appeal.ResolutionNote,
+			appeal.CreatedAt.UTC(),
+			resolvedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("insert chat appeal %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+func (r *postgresRepository) importSnapshotModerationActions(ctx context.Context, im *snapshotImporter, actions map[string]models.ModerationAction) error {
+	ids := make([]string, 0, len(actions))
+	for id := range actions {
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	for _, id := range ids {
+		action := actions[id]
+		var actor any
+		if action.ActorID != "" {
+			actor = action.ActorID
+		}
+		metadata := action.Metadata
+		if metadata == nil {
+			metadata = map[string]string{}
+		}
+		metadataJSON, err := json.Marshal(metadata)
+		if err != nil {
+			return fmt.Errorf("encode moderation action %s metadata: %w", id, err)
+		}
+		_, err = im.exec(ctx, "moderation_actions", id, "INSERT INTO moderation_actions (id, channel_id, target_id, actor_id, action, reason, metadata, seq, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) ON CONFLICT (id) DO NOTHING",
+			id,
+			action.ChannelID,
+			action.TargetID,
+			actor,
+			action.Action,
+			action.Reason,
+			metadataJSON,
+			action.Seq,
+			action.CreatedAt.UTC(),
+		)
+		if err != nil {
+			return fmt.Errorf("insert moderation action %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
 func lookupString(container map[string]map[string]string, channelID, userID string) string {
 	if container == nil {
 		return ""
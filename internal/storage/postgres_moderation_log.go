@@ -0,0 +1,89 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"bitriver-live/internal/models"
+)
+
+// insertModerationAction appends an entry to moderation_actions inside the
+// caller's transaction. Entries whose ID is already logged are skipped.
+func insertModerationAction(ctx context.Context, tx pgx.Tx, action models.ModerationAction) error {
+	if action.ID == "" {
+		id, err := generateID()
+		if err != nil {
+			return err
+		}
+		action.ID = id
+	}
+	metadata := action.Metadata
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	metadataJSON, err := json.Marshal(metadata)
+	if err != nil {
+		return fmt.Errorf("encode moderation metadata: %w", err)
+	}
+	var actorParam any
+	if action.ActorID != "" {
+		actorParam = action.ActorID
+	}
+	if _, err := tx.Exec(ctx, "INSERT INTO moderation_actions (id, channel_id, target_id, actor_id, action, reason, metadata, seq, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) ON CONFLICT (id) DO NOTHING",
+		action.ID, action.ChannelID, action.TargetID, actorParam, action.Action, action.Reason, metadataJSON, action.Seq, action.CreatedAt.UTC()); err != nil {
+		return fmt.Errorf("log moderation action: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresRepository) ListModerationHistory(channelID, targetID string, opts ModerationHistoryOptions) (ModerationHistoryPage, error) {
+	if r == nil || r.pool == nil {
+		return ModerationHistoryPage{}, ErrPostgresUnavailable
+	}
+	page := ModerationHistoryPage{Actions: make([]models.ModerationAction, 0)}
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		if err := conn.QueryRow(ctx, "SELECT COUNT(*) FROM moderation_actions WHERE channel_id = $1 AND target_id = $2", channelID, targetID).Scan(&page.Total); err != nil {
+			return err
+		}
+		query, args := appendLimitOffset(
+			"SELECT id, channel_id, target_id, COALESCE(actor_id, ''), action, reason, metadata, created_at FROM moderation_actions WHERE channel_id = $1 AND target_id = $2 ORDER BY created_at DESC, id DESC",
+			[]interface{}{channelID, targetID},
+			UserListOptions{Limit: opts.Limit, Offset: opts.Offset},
+		)
+		rows, err := conn.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var (
+				action       models.ModerationAction
+				metadataJSON []byte
+				createdAt    time.Time
+			)
+			if err := rows.Scan(&action.ID, &action.ChannelID, &action.TargetID, &action.ActorID, &action.Action, &action.Reason, &metadataJSON, &createdAt); err != nil {
+				return err
+			}
+			if len(metadataJSON) > 0 {
+				if err := json.Unmarshal(metadataJSON, &action.Metadata); err != nil {
+					return fmt.Errorf("decode moderation metadata %s: %w", action.ID, err)
+				}
+			}
+			if len(action.Metadata) == 0 {
+				action.Metadata = nil
+			}
+			action.CreatedAt = createdAt.UTC()
+			page.Actions = append(page.Actions, action)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return ModerationHistoryPage{}, fmt.Errorf("list moderation history: %w", err)
+	}
+	return page, nil
+}
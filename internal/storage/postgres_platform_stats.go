@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// platformStatsQuery aggregates the rollup in one statement. $1 is the
+// rollup time, $2 the start of its UTC day, and $3 the start of its UTC
+// month. Chat messages and sessions are only read within those windows so
+// the rollup stays cheap however long their history grows.
+const platformStatsQuery = `
+SELECT
+    (SELECT COUNT(*) FROM channels),
+    (SELECT COUNT(*) FROM channels WHERE live_state = 'live'),
+    (SELECT COUNT(*) FROM users WHERE deactivated_at IS NULL AND created_at <= $1),
+    (SELECT COUNT(*) FROM users WHERE deactivated_at IS NULL AND created_at >= $2 AND created_at <= $1),
+    (SELECT COUNT(*) FROM stream_sessions WHERE started_at >= $2 AND started_at <= $1),
+    (SELECT COALESCE(SUM(FLOOR(EXTRACT(EPOCH FROM
+        LEAST(COALESCE(ended_at, $1), $1) - GREATEST(started_at, $3)))), 0)::BIGINT
+        FROM stream_sessions
+        WHERE started_at < $1 AND (ended_at IS NULL OR ended_at > $3)),
+    (SELECT COUNT(*) FROM chat_messages WHERE created_at >= $2 AND created_at <= $1)`
+
+const platformStatsColumns = "day, total_channels, live_channels, registered_users, new_users_today, sessions_started_today, streamed_seconds_month, chat_messages_today, computed_at"
+
+// platformStatsSelect is platformStatsColumns with day read back as text.
+const platformStatsSelect = "to_char(day, 'YYYY-MM-DD'), total_channels, live_channels, registered_users, new_users_today, sessions_started_today, streamed_seconds_month, chat_messages_today, computed_at"
+
+func scanPlatformStats(row pgx.Row) (PlatformStats, error) {
+	var stats PlatformStats
+	if err := row.Scan(&stats.Day, &stats.TotalChannels, &stats.LiveChannels, &stats.RegisteredUsers, &stats.NewUsersToday, &stats.SessionsStartedToday, &stats.StreamedSecondsThisMonth, &stats.ChatMessagesToday, &stats.ComputedAt); err != nil {
+		return PlatformStats{}, err
+	}
+	stats.ComputedAt = stats.ComputedAt.UTC()
+	return stats, nil
+}
+
+func (r *postgresRepository) RollupPlatformStats(ctx context.Context, now time.Time) (PlatformStats, error) {
+	if r == nil || r.pool == nil {
+		return PlatformStats{}, ErrPostgresUnavailable
+	}
+	if err := ctx.Err(); err != nil {
+		return PlatformStats{}, err
+	}
+	now, dayStart, monthStart := platformStatsWindow(now)
+	stats := PlatformStats{Day: dayStart.Format("2006-01-02"), ComputedAt: now}
+	err := r.withTx(txSpec{Name: "rollup platform stats", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		if err := tx.QueryRow(ctx, platformStatsQuery, now, dayStart, monthStart).Scan(
+			&stats.TotalChannels,
+			&stats.LiveChannels,
+			&stats.RegisteredUsers,
+			&stats.NewUsersToday,
+			&stats.SessionsStartedToday,
+			&stats.StreamedSecondsThisMonth,
+			&stats.ChatMessagesToday,
+		); err != nil {
+			return fmt.Errorf("aggregate platform stats: %w", err)
+		}
+		_, err := tx.Exec(ctx, "INSERT INTO platform_stats ("+platformStatsColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) ON CONFLICT (day) DO UPDATE SET total_channels = EXCLUDED.total_channels, live_channels = EXCLUDED.live_channels, registered_users = EXCLUDED.registered_users, new_users_today = EXCLUDED.new_users_today, sessions_started_today = EXCLUDED.sessions_started_today, streamed_seconds_month = EXCLUDED.streamed_seconds_month, chat_messages_today = EXCLUDED.chat_messages_today, computed_at = EXCLUDED.computed_at",
+			stats.Day,
+			stats.TotalChannels,
+			stats.LiveChannels,
+			stats.RegisteredUsers,
+			stats.NewUsersToday,
+			stats.SessionsStartedToday,
+			stats.StreamedSecondsThisMonth,
+			stats.ChatMessagesToday,
+			stats.ComputedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("store platform stats: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return PlatformStats{}, err
+	}
+	return stats, nil
+}
+
+func (r *postgresRepository) LatestPlatformStats() (PlatformStats, bool) {
+	if r == nil || r.pool == nil {
+		return PlatformStats{}, false
+	}
+	var stats PlatformStats
+	err := r.withReadConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		loaded, err := scanPlatformStats(conn.QueryRow(ctx, "SELECT "+platformStatsSelect+" FROM platform_stats ORDER BY computed_at DESC LIMIT 1"))
+		if err != nil {
+			return err
+		}
+		stats = loaded
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			slog.Default().Warn("load platform stats failed", "error", err)
+		}
+		return PlatformStats{}, false
+	}
+	return stats, true
+}
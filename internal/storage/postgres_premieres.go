@@ -0,0 +1,104 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"bitriver-live/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+type duePremiere struct {
+	id         string
+	premiereAt time.Time
+}
+
+func (r *postgresRepository) StartDuePremieres(now time.Time) ([]models.Recording, error) {
+	if r == nil || r.pool == nil {
+		return nil, ErrPostgresUnavailable
+	}
+	var ids []string
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		return r.runTx(ctx, conn, txSpec{Name: "start due premieres", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+			ids = ids[:0]
+			// SKIP LOCKED lets replicas running the job side by side split
+			// the due premieres instead of queueing behind each other.
+			rows, err := tx.Query(ctx, "SELECT id, premiere_at FROM recordings WHERE premiere_at <= $1 AND published_at IS NULL AND over_quota = FALSE ORDER BY premiere_at, id FOR UPDATE SKIP LOCKED", now)
+			if err != nil {
+				return fmt.Errorf("list due premieres: %w", err)
+			}
+			var due []duePremiere
+			for rows.Next() {
+				var entry duePremiere
+				if err := rows.Scan(&entry.id, &entry.premiereAt); err != nil {
+					rows.Close()
+					return fmt.Errorf("scan due premiere: %w", err)
+				}
+				due = append(due, entry)
+			}
+			rows.Close()
+			if err := rows.Err(); err != nil {
+				return fmt.Errorf("iterate due premieres: %w", err)
+			}
+			for _, entry := range due {
+				publishedAt := entry.premiereAt.UTC()
+				if _, err := tx.Exec(ctx, "UPDATE recordings SET published_at = $1, retain_until = $2 WHERE id = $3", publishedAt, r.recordingDeadline(publishedAt, true), entry.id); err != nil {
+					return fmt.Errorf("start premiere of recording %s: %w", entry.id, err)
+				}
+				ids = append(ids, entry.id)
+			}
+			return nil
+		})
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.loadRecordings(ids)
+}
+
+func (r *postgresRepository) ListPremieringRecordings(now time.Time) ([]models.Recording, error) {
+	if r == nil || r.pool == nil {
+		return nil, ErrPostgresUnavailable
+	}
+	var ids []string
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		rows, err := conn.Query(ctx, "SELECT id FROM recordings WHERE premiere_at <= $1 AND premiere_at + duration_seconds * INTERVAL '1 second' > $1 AND published_at IS NOT NULL ORDER BY premiere_at, id", now)
+		if err != nil {
+			return fmt.Errorf("list premiering recordings: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var id string
+			if err := rows.Scan(&id); err != nil {
+				return fmt.Errorf("scan premiering recording: %w", err)
+			}
+			ids = append(ids, id)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return r.loadRecordings(ids)
+}
+
+// loadRecordings loads the listed recordings in order, skipping any deleted
+// since their ids were read.
+func (r *postgresRepository) loadRecordings(ids []string) ([]models.Recording, error) {
+	recordings := make([]models.Recording, 0, len(ids))
+	for _, id := range ids {
+		ctx, cancel := r.acquireContext()
+		recording, ok, err := r.loadRecording(ctx, id)
+		cancel()
+		if err != nil {
+			return nil, err
+		}
+		if ok {
+			recordings = append(recordings, recording)
+		}
+	}
+	return recordings, nil
+}
@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// recommendChannelsQuery scores every channel that shares viewers or a
+// category with the user's follows in one pass. pairs holds one row per
+// (candidate, followed channel, viewer) where the viewer follows both, so
+// overlap counts distinct viewers per candidate and via keeps the followed
+// channel with the most of them.
+const recommendChannelsQuery = `
+WITH mine AS (
+    SELECT channel_id FROM follows WHERE user_id = $1
+), pairs AS (
+    SELECT theirs.channel_id, ours.channel_id AS via_channel_id, theirs.user_id
+    FROM mine
+    JOIN follows ours ON ours.channel_id = mine.channel_id AND ours.user_id <> $1
+    JOIN follows theirs ON theirs.user_id = ours.user_id
+    WHERE theirs.channel_id NOT IN (SELECT channel_id FROM mine)
+), overlap AS (
+    SELECT channel_id, COUNT(DISTINCT user_id) AS peers FROM pairs GROUP BY channel_id
+), via AS (
+    SELECT DISTINCT ON (channel_id) channel_id, via_channel_id
+    FROM pairs
+    GROUP BY channel_id, via_channel_id
+    ORDER BY channel_id, COUNT(*) DESC, via_channel_id
+), affinity AS (
+    SELECT c.category, COUNT(*) AS weight
+    FROM mine JOIN channels c ON c.id = mine.channel_id
+    WHERE COALESCE(c.category, '') <> ''
+    GROUP BY c.category
+)
+SELECT c.id, COALESCE(o.peers, 0), COALESCE(v.via_channel_id, ''), COALESCE(c.category, '')
+FROM channels c
+JOIN users u ON u.id = c.owner_id AND u.deactivated_at IS NULL
+LEFT JOIN overlap o ON o.channel_id = c.id
+LEFT JOIN via v ON v.channel_id = c.id
+LEFT JOIN affinity a ON a.category = c.category
+WHERE (o.peers IS NOT NULL OR a.weight IS NOT NULL)
+  AND c.owner_id <> $1
+  AND c.id NOT IN (SELECT channel_id FROM mine)
+ORDER BY COALESCE(o.peers, 0) * $3 + COALESCE(a.weight, 0) * $4
+    + CASE WHEN c.live_state IN ('live', 'starting') THEN $5 ELSE 0 END DESC,
+  (SELECT COUNT(*) FROM follows f WHERE f.channel_id = c.id) DESC,
+  c.created_at ASC, c.id ASC
+LIMIT $2`
+
+// popularChannelsQuery is the cold-start fallback: the most-followed live
+// channels the user neither follows nor owns.
+const popularChannelsQuery = `
+SELECT c.id, COALESCE(c.category, '')
+FROM channels c
+JOIN users u ON u.id = c.owner_id AND u.deactivated_at IS NULL
+WHERE c.live_state IN ('live', 'starting')
+  AND c.owner_id <> $1
+  AND NOT EXISTS (SELECT 1 FROM follows f WHERE f.user_id = $1 AND f.channel_id = c.id)
+ORDER BY (SELECT COUNT(*) FROM follows f WHERE f.channel_id = c.id) DESC, c.created_at ASC, c.id ASC
+LIMIT $2`
+
+func (r *postgresRepository) RecommendChannels(userID string, limit int) ([]ChannelRecommendation, error) {
+	if r == nil || r.pool == nil {
+		return nil, ErrPostgresUnavailable
+	}
+	userID = strings.TrimSpace(userID)
+	limit = normalizeRecommendationLimit(limit)
+	recommendations := make([]ChannelRecommendation, 0)
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		rows, err := conn.Query(ctx, recommendChannelsQuery, userID, limit, recommendationOverlapWeight, recommendationCategoryWeight, recommendationLiveBoost)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var (
+				recommendation ChannelRecommendation
+				peers          int
+			)
+			if err := rows.Scan(&recommendation.ChannelID, &peers, &recommendation.ViaChannelID, &recommendation.Category); err != nil {
+				return err
+			}
+			recommendation.Reason = RecommendationReasonCategory
+			if peers > 0 {
+				recommendation.Reason = RecommendationReasonFollowOverlap
+			} else {
+				recommendation.ViaChannelID = ""
+			}
+			recommendations = append(recommendations, recommendation)
+		}
+		if err := rows.Err(); err != nil {
+			return err
+		}
+		if len(recommendations) > 0 {
+			return nil
+		}
+
+		rows, err = conn.Query(ctx, popularChannelsQuery, userID, limit)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			recommendation := ChannelRecommendation{Reason: RecommendationReasonPopular}
+			if err := rows.Scan(&recommendation.ChannelID, &recommendation.Category); err != nil {
+				return err
+			}
+			recommendations = append(recommendations, recommendation)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, fmt.Errorf("recommend channels: %w", err)
+	}
+	return recommendations, nil
+}
@@ -0,0 +1,108 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+
+	"bitriver-live/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func encodeRecordingChapters(chapters []models.RecordingChapter) ([]byte, error) {
+	if chapters == nil {
+		chapters = []models.RecordingChapter{}
+	}
+	encoded, err := json.Marshal(chapters)
+	if err != nil {
+		return nil, fmt.Errorf("encode recording chapters: %w", err)
+	}
+	return encoded, nil
+}
+
+func decodeRecordingChapters(data []byte) ([]models.RecordingChapter, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var chapters []models.RecordingChapter
+	if err := json.Unmarshal(data, &chapters); err != nil {
+		return nil, fmt.Errorf("decode recording chapters: %w", err)
+	}
+	if len(chapters) == 0 {
+		return nil, nil
+	}
+	return chapters, nil
+}
+
+// sessionChapters builds the starting chapters of a session's recording from
+// its stream markers.
+func sessionChapters(ctx context.Context, tx pgx.Tx, sessionID string, duration int) ([]models.RecordingChapter, error) {
+	rows, err := tx.Query(ctx, "SELECT "+streamMarkerColumns+" FROM stream_markers WHERE session_id = $1 ORDER BY offset_seconds, created_at, id", sessionID)
+	if err != nil {
+		return nil, fmt.Errorf("load stream markers: %w", err)
+	}
+	defer rows.Close()
+	var markers []models.StreamMarker
+	for rows.Next() {
+		marker, err := scanStreamMarker(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan stream marker: %w", err)
+		}
+		markers = append(markers, marker)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate stream markers: %w", err)
+	}
+	return chaptersFromMarkers(markers, duration), nil
+}
+
+func (r *postgresRepository) SetRecordingChapters(id string, chapters []models.RecordingChapter) (models.Recording, error) {
+	if r == nil || r.pool == nil {
+		return models.Recording{}, ErrPostgresUnavailable
+	}
+
+	var recording models.Recording
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		err := r.runTx(ctx, conn, txSpec{Name: "set recording chapters", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+			var duration int
+			err := tx.QueryRow(ctx, "SELECT duration_seconds FROM recordings WHERE id = $1 FOR UPDATE", id).Scan(&duration)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return notFound("recording", id)
+			}
+			if err != nil {
+				return fmt.Errorf("load recording %s: %w", id, err)
+			}
+			normalized, err := normalizeRecordingChapters(chapters, duration)
+			if err != nil {
+				return err
+			}
+			chaptersJSON, err := encodeRecordingChapters(normalized)
+			if err != nil {
+				return err
+			}
+			if _, err := tx.Exec(ctx, "UPDATE recordings SET chapters = $1 WHERE id = $2", chaptersJSON, id); err != nil {
+				return fmt.Errorf("update recording %s chapters: %w", id, err)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		rec, ok, loadErr := r.loadRecording(ctx, id)
+		if loadErr != nil {
+			return loadErr
+		}
+		if !ok {
+			return notFound("recording", id)
+		}
+		recording = rec
+		return nil
+	})
+	if err != nil {
+		return models.Recording{}, err
+	}
+	return recording, nil
+}
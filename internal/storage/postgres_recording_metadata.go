@@ -0,0 +1,133 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"bitriver-live/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func (r *postgresRepository) UpdateRecording(id string, update RecordingUpdate) (models.Recording, error) {
+	if r == nil || r.pool == nil {
+		return models.Recording{}, ErrPostgresUnavailable
+	}
+	normalized, err := update.normalized()
+	if err != nil {
+		return models.Recording{}, err
+	}
+
+	var recording models.Recording
+	err = r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		err := r.runTx(ctx, conn, txSpec{Name: "update recording", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+			var (
+				current     models.Recording
+				publishedAt pgtype.Timestamptz
+				premiereAt  pgtype.Timestamptz
+			)
+			err := tx.QueryRow(ctx, "SELECT title, description, tags, published_at, over_quota, premiere_at FROM recordings WHERE id = $1 FOR UPDATE", id).
+				Scan(&current.Title, &current.Description, &current.Tags, &publishedAt, &current.OverQuota, &premiereAt)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return notFound("recording", id)
+			}
+			if err != nil {
+				return fmt.Errorf("load recording %s: %w", id, err)
+			}
+			if publishedAt.Valid {
+				ts := publishedAt.Time.UTC()
+				current.PublishedAt = &ts
+			}
+			if premiereAt.Valid {
+				ts := premiereAt.Time.UTC()
+				current.PremiereAt = &ts
+			}
+			if err := normalized.checkPremiere(current); err != nil {
+				return err
+			}
+			normalized.apply(&current)
+			if _, err := tx.Exec(ctx, "UPDATE recordings SET title = $1, description = $2, tags = $3, premiere_at = $4 WHERE id = $5", current.Title, current.Description, normalizeTags(current.Tags), current.PremiereAt, id); err != nil {
+				return fmt.Errorf("update recording %s: %w", id, err)
+			}
+			if current.PremiereAt != nil {
+				// Keep the unpublished recording until its premiere has had a
+				// full unpublished window of its own.
+				if deadline := r.recordingDeadline(*current.PremiereAt, false); deadline != nil {
+					if _, err := tx.Exec(ctx, "UPDATE recordings SET retain_until = GREATEST(retain_until, $1) WHERE id = $2 AND retain_until IS NOT NULL", *deadline, id); err != nil {
+						return fmt.Errorf("update recording retention: %w", err)
+					}
+				}
+			}
+			return nil
+		})
+		if err != nil {
+			return err
+		}
+		rec, ok, loadErr := r.loadRecording(ctx, id)
+		if loadErr != nil {
+			return loadErr
+		}
+		if !ok {
+			return notFound("recording", id)
+		}
+		recording = rec
+		return nil
+	})
+	if err != nil {
+		return models.Recording{}, err
+	}
+	return recording, nil
+}
+
+// BatchUpdateRecordings applies update to every listed recording of the
+// channel in a single transaction. Unknown recordings are reported per
+// recording; any database error rolls the whole batch back.
+func (r *postgresRepository) BatchUpdateRecordings(channelID string, ids []string, update RecordingBatchUpdate) ([]RecordingBatchResult, error) {
+	if r == nil || r.pool == nil {
+		return nil, ErrPostgresUnavailable
+	}
+	normalized, err := update.normalized()
+	if err != nil {
+		return nil, err
+	}
+	var results []RecordingBatchResult
+	err = r.withTx(txSpec{Name: "batch update recordings", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		results = make([]RecordingBatchResult, 0, len(ids))
+		for _, id := range uniqueIDs(ids) {
+			current := models.Recording{ID: id}
+			var tags []string
+			err := tx.QueryRow(ctx, "SELECT description, tags FROM recordings WHERE id = $1 AND channel_id = $2 FOR UPDATE", id, channelID).Scan(&current.Description, &tags)
+			if errors.Is(err, pgx.ErrNoRows) {
+				results = append(results, RecordingBatchResult{RecordingID: id, Status: RecordingBatchFailed, Error: fmt.Sprintf("recording %s not found", id)})
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("load recording %s: %w", id, err)
+			}
+			if len(tags) > 0 {
+				current.Tags = tags
+			}
+			next, changed, err := normalized.apply(current)
+			if err != nil {
+				results = append(results, RecordingBatchResult{RecordingID: id, Status: RecordingBatchFailed, Error: err.Error()})
+				continue
+			}
+			if !changed {
+				results = append(results, RecordingBatchResult{RecordingID: id, Status: RecordingBatchSkipped})
+				continue
+			}
+			if _, err := tx.Exec(ctx, "UPDATE recordings SET description = $1, tags = $2 WHERE id = $3", next.Description, normalizeTags(next.Tags), id); err != nil {
+				return fmt.Errorf("update recording %s: %w", id, err)
+			}
+			results = append(results, RecordingBatchResult{RecordingID: id, Status: RecordingBatchUpdated})
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return results, nil
+}
@@ -0,0 +1,18 @@
+package storage
+
+import (
+	"time"
+)
+
+// RecordingPlayback presigns the manifests of a recording archived to object
+// storage.
+func (r *postgresRepository) RecordingPlayback(id string, ttl time.Duration) (RecordingPlayback, error) {
+	if r == nil || r.pool == nil {
+		return RecordingPlayback{}, ErrPostgresUnavailable
+	}
+	recording, ok := r.GetRecording(id)
+	if !ok {
+		return RecordingPlayback{}, notFound("recording", id)
+	}
+	return presignRecordingPlayback(r.objectClient, recording, ttl, time.Now().UTC())
+}
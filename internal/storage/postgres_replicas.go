@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// Read replicas take lag-tolerant reads off the primary. Streaming
+// replication usually trails the primary by well under a second, but a busy
+// or recovering replica can fall further behind, so only reads whose callers
+// can live with a slightly old answer are routed to them: the channel
+// directory, profile listings, follower counts and lists, chat history and
+// the pinned message, and clip listings. Everything else stays on the
+// primary, notably:
+//
+//   - writes, and reads made inside write transactions;
+//   - GetUser, GetChannel, and the session and token lookups behind
+//     authorization, where a stale row could let a revoked role or a
+//     deleted channel through;
+//   - GetUpload, GetRecording, and ListRecordings, which clients poll right
+//     after creating or publishing, and whose recording reads purge expired
+//     recordings first;
+//   - any read made after ContextWithPrimaryReads marks the request.
+
+const (
+	// replicaAcquireTimeout bounds how long a read waits on one replica
+	// before falling back, so an unreachable replica cannot spend the whole
+	// acquire timeout meant for the primary.
+	replicaAcquireTimeout = 2 * time.Second
+	// replicaRetryInterval is how long a replica that failed to hand out a
+	// connection is skipped before reads try it again.
+	replicaRetryInterval = 10 * time.Second
+)
+
+// connAcquirer hands out pooled connections. *pgxpool.Pool satisfies it;
+// tests substitute fakes to see where reads are routed.
+type connAcquirer interface {
+	Acquire(ctx context.Context) (*pgxpool.Conn, error)
+}
+
+// readReplicas spreads reads over a set of replica pools round-robin,
+// skipping replicas that recently failed.
+type readReplicas struct {
+	mu        sync.Mutex
+	pools     []connAcquirer
+	downUntil []time.Time
+	next      int
+	now       func() time.Time
+}
+
+func newReadReplicas(pools []connAcquirer) *readReplicas {
+	if len(pools) == 0 {
+		return nil
+	}
+	return &readReplicas{
+		pools:     pools,
+		downUntil: make([]time.Time, len(pools)),
+		now:       time.Now,
+	}
+}
+
+// acquire returns a connection from the next healthy replica. It reports
+// false when every replica is down, leaving the caller to use the primary.
+func (s *readReplicas) acquire(ctx context.Context) (*pgxpool.Conn, bool) {
+	for range s.pools {
+		index, ok := s.pick()
+		if !ok {
+			return nil, false
+		}
+		attemptCtx, cancel := context.WithTimeout(ctx, replicaAcquireTimeout)
+		conn, err := s.pools[index].Acquire(attemptCtx)
+		cancel()
+		if err == nil {
+			return conn, true
+		}
+		s.markDown(index)
+		slog.Default().Warn("postgres read replica unavailable, falling back", "replica", index, "retry_in", replicaRetryInterval, "error", err)
+		if ctx.Err() != nil {
+			return nil, false
+		}
+	}
+	return nil, false
+}
+
+// pick advances the round-robin cursor to the next replica that is not
+// marked down.
+func (s *readReplicas) pick() (int, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.now()
+	for range s.pools {
+		index := s.next
+		s.next = (s.next + 1) % len(s.pools)
+		if !now.Before(s.downUntil[index]) {
+			return index, true
+		}
+	}
+	return 0, false
+}
+
+func (s *readReplicas) markDown(index int) {
+	s.mu.Lock()
+	s.downUntil[index] = s.now().Add(replicaRetryInterval)
+	s.mu.Unlock()
+}
+
+func (s *readReplicas) close() {
+	for _, pool := range s.pools {
+		if closer, ok := pool.(interface{ Close() }); ok {
+			closer.Close()
+		}
+	}
+}
+
+// openReadReplicas opens a pool per replica DSN with the primary's pool
+// settings.
+func openReadReplicas(ctx context.Context, cfg PostgresConfig) (*readReplicas, error) {
+	pools := make([]connAcquirer, 0, len(cfg.ReplicaDSNs))
+	for i, dsn := range cfg.ReplicaDSNs {
+		poolCfg, err := postgresPoolConfig(dsn, cfg)
+		if err != nil {
+			closeReplicaPools(pools)
+			return nil, fmt.Errorf("parse postgres replica %d config: %w", i, err)
+		}
+		pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
+		if err != nil {
+			closeReplicaPools(pools)
+			return nil, fmt.Errorf("open postgres replica %d pool: %w", i, err)
+		}
+		pools = append(pools, pool)
+	}
+	return newReadReplicas(pools), nil
+}
+
+func closeReplicaPools(pools []connAcquirer) {
+	if replicas := newReadReplicas(pools); replicas != nil {
+		replicas.close()
+	}
+}
+
+// acquirePrimaryConn returns a connection to the primary.
+func (r *postgresRepository) acquirePrimaryConn(ctx context.Context) (*pgxpool.Conn, error) {
+	var pool connAcquirer = r.pool
+	if r.primary != nil {
+		pool = r.primary
+	}
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("acquire postgres connection: %w", err)
+	}
+	return conn, nil
+}
+
+// acquireReadConn returns a connection for a read that tolerates replication
+// lag: a replica when one is configured and reachable and the repository is
+// not pinned to the primary, otherwise the primary.
+func (r *postgresRepository) acquireReadConn(ctx context.Context) (*pgxpool.Conn, error) {
+	if r.replicas != nil && !r.primaryReads {
+		if conn, ok := r.replicas.acquire(ctx); ok {
+			return conn, nil
+		}
+	}
+	return r.acquirePrimaryConn(ctx)
+}
+
+// withReadConn is withConn for reads that tolerate replication lag; see
+// acquireReadConn.
+func (r *postgresRepository) withReadConn(fn func(context.Context, *pgxpool.Conn) error) error {
+	if r == nil || r.pool == nil {
+		return ErrPostgresUnavailable
+	}
+	ctx, cancel := r.acquireContext()
+	defer cancel()
+	conn, err := r.acquireReadConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	return fn(ctx, conn)
+}
+
+// withPrimaryReads returns a view of the repository that serves every read
+// from the primary. It shares the pools and state of r.
+func (r *postgresRepository) withPrimaryReads() Repository {
+	if r == nil || r.replicas == nil || r.primaryReads {
+		return r
+	}
+	view := *r
+	view.primaryReads = true
+	return &view
+}
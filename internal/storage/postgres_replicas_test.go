@@ -0,0 +1,115 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// fakeConnPool counts the connections it hands out. The connections are the
+// pgx stub's, whose queries find no rows.
+type fakeConnPool struct {
+	acquired int
+	err      error
+}
+
+func (p *fakeConnPool) Acquire(context.Context) (*pgxpool.Conn, error) {
+	p.acquired++
+	if p.err != nil {
+		return nil, p.err
+	}
+	return new(pgxpool.Pool).Acquire(context.Background())
+}
+
+func newReplicaTestRepository(primary *fakeConnPool, replicas ...*fakeConnPool) *postgresRepository {
+	repo := newTxTestRepository()
+	repo.pool = new(pgxpool.Pool)
+	repo.primary = primary
+	pools := make([]connAcquirer, 0, len(replicas))
+	for _, replica := range replicas {
+		pools = append(pools, replica)
+	}
+	repo.replicas = newReadReplicas(pools)
+	return repo
+}
+
+func TestReadReplicasServeReadsAndPrimaryServesWrites(t *testing.T) {
+	primary, first, second := &fakeConnPool{}, &fakeConnPool{}, &fakeConnPool{}
+	repo := newReplicaTestRepository(primary, first, second)
+
+	if _, err := repo.ListChannels("", ""); err != nil {
+		t.Fatalf("ListChannels: %v", err)
+	}
+	if _, err := repo.ListProfiles(); err != nil {
+		t.Fatalf("ListProfiles: %v", err)
+	}
+	if first.acquired != 1 || second.acquired != 1 || primary.acquired != 0 {
+		t.Fatalf("expected reads spread over both replicas, got primary=%d first=%d second=%d", primary.acquired, first.acquired, second.acquired)
+	}
+
+	// The stub transaction finds no user, so the write fails, but only after
+	// it has been sent to the primary.
+	_ = repo.FollowChannel("user-1", "channel-1")
+	if _, ok := repo.GetChannel("channel-1"); ok {
+		t.Fatal("expected the stub to find no channel")
+	}
+	if primary.acquired != 2 || first.acquired != 1 || second.acquired != 1 {
+		t.Fatalf("expected the write and the primary-only read on the primary, got primary=%d first=%d second=%d", primary.acquired, first.acquired, second.acquired)
+	}
+}
+
+func TestReadReplicasFallBackToPrimaryWhileDown(t *testing.T) {
+	primary, replica := &fakeConnPool{}, &fakeConnPool{err: errors.New("connection refused")}
+	repo := newReplicaTestRepository(primary, replica)
+	now := time.Date(2026, 3, 1, 12, 0, 0, 0, time.UTC)
+	repo.replicas.now = func() time.Time { return now }
+
+	if _, err := repo.ListChatMessages("channel-1", 10); err == nil {
+		t.Fatal("expected the stub primary to find no channel")
+	}
+	if replica.acquired != 1 || primary.acquired != 1 {
+		t.Fatalf("expected the read to fall back to the primary, got primary=%d replica=%d", primary.acquired, replica.acquired)
+	}
+
+	repo.CountFollowers("channel-1")
+	if replica.acquired != 1 || primary.acquired != 2 {
+		t.Fatalf("expected the down replica to be skipped, got primary=%d replica=%d", primary.acquired, replica.acquired)
+	}
+
+	replica.err = nil
+	now = now.Add(replicaRetryInterval)
+	repo.CountFollowers("channel-1")
+	if replica.acquired != 2 || primary.acquired != 2 {
+		t.Fatalf("expected the replica to be retried after the interval, got primary=%d replica=%d", primary.acquired, replica.acquired)
+	}
+}
+
+func TestPrimaryReadsContextPinsReadsToPrimary(t *testing.T) {
+	primary, replica := &fakeConnPool{}, &fakeConnPool{}
+	repo := newReplicaTestRepository(primary, replica)
+
+	if got := RepositoryForContext(context.Background(), repo); got != Repository(repo) {
+		t.Fatal("expected unmarked requests to keep the replica-backed repository")
+	}
+	view := RepositoryForContext(ContextWithPrimaryReads(context.Background()), repo)
+	view.CountFollowers("channel-1")
+	if _, err := view.ListProfiles(); err != nil {
+		t.Fatalf("ListProfiles: %v", err)
+	}
+	if primary.acquired != 2 || replica.acquired != 0 {
+		t.Fatalf("expected reads after a write on the primary, got primary=%d replica=%d", primary.acquired, replica.acquired)
+	}
+
+	repo.CountFollowers("channel-1")
+	if replica.acquired != 1 {
+		t.Fatalf("expected the shared repository to keep reading from the replica, got replica=%d", replica.acquired)
+	}
+
+	store := newTestStore(t)
+	if got := RepositoryForContext(ContextWithPrimaryReads(context.Background()), store); got != Repository(store) {
+		t.Fatal("expected repositories without replicas to be returned unchanged")
+	}
+}
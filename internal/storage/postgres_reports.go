@@ -0,0 +1,254 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"bitriver-live/internal/models"
+)
+
+const chatReportColumns = "id, channel_id, reporter_id, target_id, subject_type, subject_id, category, reason, message_id, evidence_url, claimant_name, claimant_email, claimant_phone, claimant_address, status, resolution, resolver_id, created_at, resolved_at"
+
+func scanChatReport(row pgx.Row) (models.ChatReport, error) {
+	var (
+		report          models.ChatReport
+		subjectID       pgtype.Text
+		messageID       pgtype.Text
+		evidenceURL     pgtype.Text
+		claimantName    pgtype.Text
+		claimantEmail   pgtype.Text
+		claimantPhone   pgtype.Text
+		claimantAddress pgtype.Text
+		resolution      pgtype.Text
+		resolverID      pgtype.Text
+		resolvedAt      pgtype.Timestamptz
+	)
+	if err := row.Scan(&report.ID, &report.ChannelID, &report.ReporterID, &report.TargetID, &report.SubjectType, &subjectID, &report.Category, &report.Reason, &messageID, &evidenceURL, &claimantName, &claimantEmail, &claimantPhone, &claimantAddress, &report.Status, &resolution, &resolverID, &report.CreatedAt, &resolvedAt); err != nil {
+		return models.ChatReport{}, err
+	}
+	report.SubjectID = subjectID.String
+	report.MessageID = messageID.String
+	report.EvidenceURL = evidenceURL.String
+	if claimantName.Valid || claimantEmail.Valid {
+		report.Claimant = &models.ReportClaimant{
+			Name:    claimantName.String,
+			Email:   claimantEmail.String,
+			Phone:   claimantPhone.String,
+			Address: claimantAddress.String,
+		}
+	}
+	report.Status = strings.ToLower(report.Status)
+	report.Resolution = resolution.String
+	report.ResolverID = resolverID.String
+	report.CreatedAt = report.CreatedAt.UTC()
+	if resolvedAt.Valid {
+		ts := resolvedAt.Time.UTC()
+		report.ResolvedAt = &ts
+	}
+	return report, nil
+}
+
+func reportText(value string) pgtype.Text {
+	return pgtype.Text{String: value, Valid: value != ""}
+}
+
+// insertChatReport stores a new report.
+func insertChatReport(ctx context.Context, tx pgx.Tx, report models.ChatReport) error {
+	var claimant models.ReportClaimant
+	if report.Claimant != nil {
+		claimant = *report.Claimant
+	}
+	_, err := tx.Exec(ctx, "INSERT INTO chat_reports (id, channel_id, reporter_id, target_id, subject_type, subject_id, category, reason, message_id, evidence_url, claimant_name, claimant_email, claimant_phone, claimant_address, status, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13, $14, $15, $16)",
+		report.ID,
+		report.ChannelID,
+		report.ReporterID,
+		report.TargetID,
+		report.SubjectType,
+		reportText(report.SubjectID),
+		report.Category,
+		report.Reason,
+		reportText(report.MessageID),
+		reportText(report.EvidenceURL),
+		reportText(claimant.Name),
+		reportText(claimant.Email),
+		reportText(claimant.Phone),
+		reportText(claimant.Address),
+		report.Status,
+		report.CreatedAt,
+	)
+	if err != nil {
+		return fmt.Errorf("insert chat report: %w", err)
+	}
+	return nil
+}
+
+// CreateReport files a report on a chat message, live session, recording, or
+// channel. The subject must belong to the channel.
+func (r *postgresRepository) CreateReport(params ReportParams) (models.ChatReport, error) {
+	if r == nil || r.pool == nil {
+		return models.ChatReport{}, ErrPostgresUnavailable
+	}
+	params, err := normalizeReportParams(params)
+	if err != nil {
+		return models.ChatReport{}, err
+	}
+
+	var report models.ChatReport
+	err = r.withTx(txSpec{Name: "create report", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		var targetID string
+		if err := tx.QueryRow(ctx, "SELECT owner_id FROM channels WHERE id = $1", params.ChannelID).Scan(&targetID); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return notFound("channel", params.ChannelID)
+			}
+			return fmt.Errorf("load channel %s: %w", params.ChannelID, err)
+		}
+		if err := ensureUserExists(ctx, tx, params.ReporterID); err != nil {
+			return err
+		}
+		switch params.SubjectType {
+		case models.ReportSubjectChatMessage:
+			err := tx.QueryRow(ctx, "SELECT user_id FROM chat_messages WHERE id = $1 AND channel_id = $2", params.SubjectID, params.ChannelID).Scan(&targetID)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return notFound("chat message", params.SubjectID)
+			}
+			if err != nil {
+				return fmt.Errorf("load chat message %s: %w", params.SubjectID, err)
+			}
+		case models.ReportSubjectStreamSession:
+			if err := ensureReportSubjectExists(ctx, tx, "stream_sessions", "stream session", params); err != nil {
+				return err
+			}
+		case models.ReportSubjectRecording:
+			if err := ensureReportSubjectExists(ctx, tx, "recordings", "recording", params); err != nil {
+				return err
+			}
+		}
+
+		created, err := newReport(params, targetID, time.Now())
+		if err != nil {
+			return err
+		}
+		if err := insertChatReport(ctx, tx, created); err != nil {
+			return err
+		}
+		report = created
+		return nil
+	})
+	if err != nil {
+		return models.ChatReport{}, err
+	}
+	return report, nil
+}
+
+// ensureReportSubjectExists checks that the reported row of table belongs to
+// the report's channel.
+func ensureReportSubjectExists(ctx context.Context, tx pgx.Tx, table, noun string, params ReportParams) error {
+	var exists bool
+	if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM "+table+" WHERE id = $1 AND channel_id = $2)", params.SubjectID, params.ChannelID).Scan(&exists); err != nil {
+		return fmt.Errorf("check %s %s: %w", noun, params.SubjectID, err)
+	}
+	if !exists {
+		return fmt.Errorf("%s %s not found", noun, params.SubjectID)
+	}
+	return nil
+}
+
+// ListReports lists reports matching filter, newest first.
+func (r *postgresRepository) ListReports(filter ReportFilter) ([]models.ChatReport, error) {
+	if r == nil || r.pool == nil {
+		return nil, ErrPostgresUnavailable
+	}
+	filter, err := normalizeReportFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	reports := make([]models.ChatReport, 0)
+	err = r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		var (
+			conditions []string
+			args       []any
+		)
+		where := func(condition string, value any) {
+			args = append(args, value)
+			conditions = append(conditions, strings.Replace(condition, "?", "$"+strconv.Itoa(len(args)), 1))
+		}
+		if filter.ChannelID != "" {
+			var exists bool
+			if err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM channels WHERE id = $1)", filter.ChannelID).Scan(&exists); err != nil {
+				return fmt.Errorf("check channel %s: %w", filter.ChannelID, err)
+			}
+			if !exists {
+				return notFound("channel", filter.ChannelID)
+			}
+			where("channel_id = ?", filter.ChannelID)
+		}
+		if filter.SubjectType != "" {
+			where("subject_type = ?", filter.SubjectType)
+		}
+		if filter.Category != "" {
+			where("category = ?", filter.Category)
+		}
+		if !filter.IncludeResolved {
+			conditions = append(conditions, "LOWER(status) <> 'resolved'")
+		}
+		query := "SELECT " + chatReportColumns + " FROM chat_reports"
+		if len(conditions) > 0 {
+			query += " WHERE " + strings.Join(conditions, " AND ")
+		}
+		query += " ORDER BY created_at DESC, id ASC"
+
+		rows, err := conn.Query(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("list reports: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			report, err := scanChatReport(rows)
+			if err != nil {
+				return fmt.Errorf("scan report: %w", err)
+			}
+			reports = append(reports, report)
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("iterate reports: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return reports, nil
+}
+
+// GetReport returns the report with the given ID.
+func (r *postgresRepository) GetReport(id string) (models.ChatReport, bool) {
+	if r == nil || r.pool == nil {
+		return models.ChatReport{}, false
+	}
+	var report models.ChatReport
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		found, err := scanChatReport(conn.QueryRow(ctx, "SELECT "+chatReportColumns+" FROM chat_reports WHERE id = $1", id))
+		if err != nil {
+			return err
+		}
+		report = found
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, pgx.ErrNoRows) {
+			slog.Default().Warn("load report failed", "report_id", id, "error", err)
+		}
+		return models.ChatReport{}, false
+	}
+	return report, true
+}
@@ -13,10 +13,12 @@ import (
 	"time"
 	"unicode/utf8"
 
+	"bitriver-live/internal/authz"
 	"bitriver-live/internal/chat"
 	"bitriver-live/internal/ingest"
 	"bitriver-live/internal/models"
 	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
 )
@@ -26,19 +28,37 @@ import (
 var ErrPostgresUnavailable = fmt.Errorf("postgres repository unavailable")
 
 type postgresRepository struct {
-	pool                *pgxpool.Pool
+	pool *pgxpool.Pool
+	// primary hands out primary connections in place of pool when set, so
+	// tests can observe where queries run.
+	primary connAcquirer
+	// replicas serves lag-tolerant reads when read replicas are configured.
+	replicas *readReplicas
+	// primaryReads pins every read to the primary; see withPrimaryReads.
+	primaryReads        bool
 	cfg                 PostgresConfig
 	ingestController    ingest.Controller
 	ingestMaxAttempts   int
 	ingestRetryInterval time.Duration
 	ingestTimeout       time.Duration
-	ingestHealthMu      sync.RWMutex
-	ingestHealth        []ingest.HealthStatus
-	ingestHealthUpdated time.Time
+	startingTimeout     time.Duration
+	ingestHealth        *ingestHealthSnapshot
 	recordingRetention  RecordingRetentionPolicy
+	storageQuota        int64
+	streamLimit         int
 	objectStorage       ObjectStorageConfig
 	objectClient        objectStorageClient
 	retentionNow        func() time.Time
+	grantNow            func() time.Time
+	secrets             *secretSealer
+}
+
+// ingestHealthSnapshot holds the last ingest health report. It lives behind
+// a pointer so primary-read views of the repository share it.
+type ingestHealthSnapshot struct {
+	mu       sync.RWMutex
+	statuses []ingest.HealthStatus
+	updated  time.Time
 }
 
 func (r *postgresRepository) Close(ctx context.Context) error {
@@ -48,6 +68,9 @@ func (r *postgresRepository) Close(ctx context.Context) error {
 	done := make(chan struct{})
 	go func() {
 		r.pool.Close()
+		if r.replicas != nil {
+			r.replicas.close()
+		}
 		close(done)
 	}()
 	select {
@@ -82,14 +105,63 @@ func NewPostgresRepository(dsn string, opts ...Option) (Repository, error) {
 	if strings.TrimSpace(cfg.DSN) == "" {
 		return nil, fmt.Errorf("postgres dsn required")
 	}
+	secrets, err := newSecretKeyring(cfg.SecretKey, cfg.EncryptionKeys)
+	if err != nil {
+		return nil, err
+	}
 	if pgx.IsStub {
 		return nil, ErrPostgresUnavailable
 	}
 
-	poolCfg, err := pgxpool.ParseConfig(cfg.DSN)
+	poolCfg, err := postgresPoolConfig(cfg.DSN, cfg)
 	if err != nil {
 		return nil, fmt.Errorf("parse postgres config: %w", err)
 	}
+
+	ctx := context.Background()
+	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
+	if err != nil {
+		return nil, fmt.Errorf("open postgres pool: %w", err)
+	}
+	replicas, err := openReadReplicas(ctx, cfg)
+	if err != nil {
+		pool.Close()
+		return nil, err
+	}
+
+	repo := &postgresRepository{
+		pool:                pool,
+		replicas:            replicas,
+		cfg:                 cfg,
+		ingestController:    cfg.IngestController,
+		ingestMaxAttempts:   cfg.IngestMaxAttempts,
+		ingestRetryInterval: cfg.IngestRetryInterval,
+		ingestTimeout:       normalizeIngestTimeout(cfg.IngestTimeout),
+		startingTimeout:     normalizeStartingTimeout(cfg.StartingTimeout),
+		ingestHealth: &ingestHealthSnapshot{
+			statuses: []ingest.HealthStatus{{Component: "ingest", Status: "disabled"}},
+			updated:  time.Now().UTC(),
+		},
+		recordingRetention: cfg.RecordingRetention,
+		storageQuota:       cfg.ChannelStorageQuota,
+		streamLimit:        cfg.MaxConcurrentStreams,
+		objectStorage:      cfg.ObjectStorage,
+		retentionNow:       cfg.RetentionClock,
+		grantNow:           cfg.GrantClock,
+		secrets:            secrets,
+	}
+	repo.objectStorage = applyObjectStorageDefaults(repo.objectStorage)
+	repo.objectClient = newObjectStorageClient(repo.objectStorage)
+	return repo, nil
+}
+
+// postgresPoolConfig parses dsn and applies the pool settings from cfg. The
+// primary and every read replica share them.
+func postgresPoolConfig(dsn string, cfg PostgresConfig) (*pgxpool.Config, error) {
+	poolCfg, err := pgxpool.ParseConfig(dsn)
+	if err != nil {
+		return nil, err
+	}
 	if cfg.MaxConnections > 0 {
 		poolCfg.MaxConns = cfg.MaxConnections
 	}
@@ -111,29 +183,7 @@ func NewPostgresRepository(dsn string, opts ...Option) (Repository, error) {
 		}
 		poolCfg.ConnConfig.RuntimeParams["application_name"] = cfg.ApplicationName
 	}
-
-	ctx := context.Background()
-	pool, err := pgxpool.NewWithConfig(ctx, poolCfg)
-	if err != nil {
-		return nil, fmt.Errorf("open postgres pool: %w", err)
-	}
-
-	repo := &postgresRepository{
-		pool:                pool,
-		cfg:                 cfg,
-		ingestController:    cfg.IngestController,
-		ingestMaxAttempts:   cfg.IngestMaxAttempts,
-		ingestRetryInterval: cfg.IngestRetryInterval,
-		ingestTimeout:       normalizeIngestTimeout(cfg.IngestTimeout),
-		ingestHealth:        []ingest.HealthStatus{{Component: "ingest", Status: "disabled"}},
-		ingestHealthUpdated: time.Now().UTC(),
-		recordingRetention:  cfg.RecordingRetention,
-		objectStorage:       cfg.ObjectStorage,
-		retentionNow:        cfg.RetentionClock,
-	}
-	repo.objectStorage = applyObjectStorageDefaults(repo.objectStorage)
-	repo.objectClient = newObjectStorageClient(repo.objectStorage)
-	return repo, nil
+	return poolCfg, nil
 }
 
 func (r *postgresRepository) IngestHealth(ctx context.Context) []ingest.HealthStatus {
@@ -148,20 +198,24 @@ func (r *postgresRepository) IngestHealth(ctx context.Context) []ingest.HealthSt
 		}
 	}
 
-	snapshot := append([]ingest.HealthStatus(nil), statuses...)
-	r.ingestHealthMu.Lock()
-	r.ingestHealth = snapshot
-	r.ingestHealthUpdated = time.Now().UTC()
-	r.ingestHealthMu.Unlock()
+	r.RecordIngestHealth(statuses)
+	return append([]ingest.HealthStatus(nil), statuses...)
+}
 
-	return snapshot
+// RecordIngestHealth replaces the snapshot returned by LastIngestHealth.
+func (r *postgresRepository) RecordIngestHealth(statuses []ingest.HealthStatus) {
+	snapshot := append([]ingest.HealthStatus(nil), statuses...)
+	r.ingestHealth.mu.Lock()
+	r.ingestHealth.statuses = snapshot
+	r.ingestHealth.updated = time.Now().UTC()
+	r.ingestHealth.mu.Unlock()
 }
 
 func (r *postgresRepository) LastIngestHealth() ([]ingest.HealthStatus, time.Time) {
-	r.ingestHealthMu.RLock()
-	defer r.ingestHealthMu.RUnlock()
-	clone := append([]ingest.HealthStatus(nil), r.ingestHealth...)
-	return clone, r.ingestHealthUpdated
+	r.ingestHealth.mu.RLock()
+	defer r.ingestHealth.mu.RUnlock()
+	clone := append([]ingest.HealthStatus(nil), r.ingestHealth.statuses...)
+	return clone, r.ingestHealth.updated
 }
 
 func (r *postgresRepository) CreateUser(params CreateUserParams) (models.User, error) {
@@ -171,12 +225,12 @@ func (r *postgresRepository) CreateUser(params CreateUserParams) (models.User, e
 
 	normalizedEmail := strings.TrimSpace(strings.ToLower(params.Email))
 	if normalizedEmail == "" {
-		return models.User{}, fmt.Errorf("email is required")
+		return models.User{}, invalid("email", "email is required")
 	}
 
 	displayName := strings.TrimSpace(params.DisplayName)
 	if displayName == "" {
-		return models.User{}, fmt.Errorf("displayName is required")
+		return models.User{}, invalid("displayName", "displayName is required")
 	}
 
 	roles := normalizeRoles(params.Roles)
@@ -185,7 +239,7 @@ func (r *postgresRepository) CreateUser(params CreateUserParams) (models.User, e
 	}
 	if params.SelfSignup {
 		if params.Password == "" {
-			return models.User{}, fmt.Errorf("password is required for self-service signup")
+			return models.User{}, invalid("password", "password is required for self-service signup")
 		}
 		if len(roles) == 0 {
 			roles = []string{"viewer"}
@@ -206,31 +260,31 @@ func (r *postgresRepository) CreateUser(params CreateUserParams) (models.User, e
 		passwordHash = hashed
 	}
 
-	var createdAt time.Time
-	createErr := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
-		if err != nil {
-			return fmt.Errorf("begin create user tx: %w", err)
-		}
-		defer rollbackTx(ctx, tx)
-
+	var (
+		createdAt         time.Time
+		username          string
+		usernameChangedAt *time.Time
+	)
+	createErr := r.withTx(txSpec{Name: "create user", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
 		var existingID string
 		err = tx.QueryRow(ctx, "SELECT id FROM users WHERE email = $1", normalizedEmail).Scan(&existingID)
 		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
 			return fmt.Errorf("check existing email: %w", err)
 		}
 		if err == nil {
-			return fmt.Errorf("email %s already in use", params.Email)
+			return conflict("email", "email %s already in use", params.Email)
 		}
 
-		err = tx.QueryRow(ctx, "INSERT INTO users (id, display_name, email, roles, password_hash, self_signup) VALUES ($1, $2, $3, $4, $5, $6) RETURNING created_at", id, displayName, normalizedEmail, roles, passwordHash, params.SelfSignup).Scan(&createdAt)
+		username, usernameChangedAt, err = chooseUsername(params, time.Now().UTC(), postgresUsernameTaken(ctx, tx, ""))
 		if err != nil {
-			return fmt.Errorf("insert user: %w", err)
+			return err
 		}
 
-		if err := tx.Commit(ctx); err != nil {
-			return fmt.Errorf("commit create user: %w", err)
+		err = tx.QueryRow(ctx, "INSERT INTO users (id, display_name, email, roles, password_hash, self_signup, username, username_changed_at, username_grace) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) RETURNING created_at", id, displayName, normalizedEmail, roles, passwordHash, params.SelfSignup, username, usernameChangedAt, usernameChangedAt == nil).Scan(&createdAt)
+		if err != nil {
+			return fmt.Errorf("insert user: %w", err)
 		}
+
 		return nil
 	})
 	if createErr != nil {
@@ -238,13 +292,16 @@ func (r *postgresRepository) CreateUser(params CreateUserParams) (models.User, e
 	}
 
 	return models.User{
-		ID:           id,
-		DisplayName:  displayName,
-		Email:        normalizedEmail,
-		Roles:        roles,
-		PasswordHash: passwordHash,
-		SelfSignup:   params.SelfSignup,
-		CreatedAt:    createdAt.UTC(),
+		ID:                id,
+		DisplayName:       displayName,
+		Username:          username,
+		Email:             normalizedEmail,
+		Roles:             roles,
+		PasswordHash:      passwordHash,
+		SelfSignup:        params.SelfSignup,
+		CreatedAt:         createdAt.UTC(),
+		UsernameChangedAt: usernameChangedAt,
+		UsernameGrace:     usernameChangedAt == nil,
 	}, nil
 }
 
@@ -259,7 +316,7 @@ func (r *postgresRepository) AuthenticateUser(email, password string) (models.Us
 	trimmedEmail := strings.TrimSpace(strings.ToLower(email))
 	var user models.User
 	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		row := conn.QueryRow(ctx, "SELECT id, display_name, email, roles, password_hash, self_signup, created_at FROM users WHERE email = $1", trimmedEmail)
+		row := conn.QueryRow(ctx, "SELECT id, display_name, email, roles, password_hash, self_signup, created_at, birth_date, age_confirmed_at, deactivated_at, username, username_changed_at, username_grace, max_concurrent_streams FROM users WHERE email = $1", trimmedEmail)
 		scanned, scanErr := scanUser(row)
 		if scanErr != nil {
 			return scanErr
@@ -282,19 +339,22 @@ func (r *postgresRepository) AuthenticateUser(email, password string) (models.Us
 		}
 		return models.User{}, err
 	}
+	if user.Deactivated() {
+		return models.User{}, ErrUserDeactivated
+	}
 	return user, nil
 }
 
-func (r *postgresRepository) ListUsers() []models.User {
+func (r *postgresRepository) ListUsers() ([]models.User, error) {
 	if r == nil || r.pool == nil {
-		return nil
+		return nil, ErrPostgresUnavailable
 	}
 
-	var users []models.User
+	users := make([]models.User, 0)
 	listErr := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		rows, err := conn.Query(ctx, "SELECT id, display_name, email, roles, password_hash, self_signup, created_at FROM users ORDER BY created_at ASC")
+		rows, err := conn.Query(ctx, "SELECT id, display_name, email, roles, password_hash, self_signup, created_at, birth_date, age_confirmed_at, deactivated_at, username, username_changed_at, username_grace, max_concurrent_streams FROM users ORDER BY created_at ASC, id ASC")
 		if err != nil {
-			return err
+			return fmt.Errorf("list users: %w", err)
 		}
 		defer rows.Close()
 
@@ -308,9 +368,106 @@ func (r *postgresRepository) ListUsers() []models.User {
 		return rows.Err()
 	})
 	if listErr != nil {
-		return nil
+		return nil, listErr
+	}
+	return users, nil
+}
+
+// ListUsersPage filters, orders, and pages users in SQL. Substring search uses
+// ILIKE, which the trigram indexes from migration 0012 keep cheap, and the
+// total comes from a separate COUNT over the same filter.
+func (r *postgresRepository) ListUsersPage(opts UserListOptions) (UserPage, error) {
+	if r == nil || r.pool == nil {
+		return UserPage{}, ErrPostgresUnavailable
+	}
+	where, order, args, err := userListClauses(opts, "")
+	if err != nil {
+		return UserPage{}, err
+	}
+	page := UserPage{Users: make([]models.User, 0)}
+	err = r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		if err := conn.QueryRow(ctx, "SELECT COUNT(*) FROM users"+where, args...).Scan(&page.Total); err != nil {
+			return err
+		}
+		if page.Total == 0 {
+			return nil
+		}
+		query, queryArgs := appendLimitOffset("SELECT id, display_name, email, roles, password_hash, self_signup, created_at, birth_date, age_confirmed_at, deactivated_at, username, username_changed_at, username_grace, max_concurrent_streams FROM users"+where+order, args, opts)
+		rows, err := conn.Query(ctx, query, queryArgs...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			user, err := scanUser(rows)
+			if err != nil {
+				return err
+			}
+			page.Users = append(page.Users, user)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return UserPage{}, fmt.Errorf("list users page: %w", err)
+	}
+	return page, nil
+}
+
+// userListClauses renders the WHERE and ORDER BY clauses shared by the user
+// and profile pages. prefix qualifies the users table columns.
+func userListClauses(opts UserListOptions, prefix string) (string, string, []interface{}, error) {
+	column, err := userSortColumn(opts.Sort, prefix)
+	if err != nil {
+		return "", "", nil, err
+	}
+	var (
+		args    []interface{}
+		clauses []string
+	)
+	if query := strings.TrimSpace(opts.Query); query != "" {
+		args = append(args, "%"+escapeLikePattern(query)+"%")
+		if opts.NameOnly {
+			clauses = append(clauses, fmt.Sprintf("%sdisplay_name ILIKE $%d", prefix, len(args)))
+		} else {
+			clauses = append(clauses, fmt.Sprintf("(%[1]semail ILIKE $%[2]d OR %[1]sdisplay_name ILIKE $%[2]d)", prefix, len(args)))
+		}
+	}
+	if email := strings.ToLower(strings.TrimSpace(opts.Email)); email != "" {
+		args = append(args, email)
+		clauses = append(clauses, fmt.Sprintf("%semail = $%d", prefix, len(args)))
+	}
+	if role := strings.ToLower(strings.TrimSpace(opts.Role)); role != "" {
+		args = append(args, []string{role})
+		clauses = append(clauses, fmt.Sprintf("%sroles @> $%d::text[]", prefix, len(args)))
+	}
+	var where string
+	if len(clauses) > 0 {
+		where = " WHERE " + strings.Join(clauses, " AND ")
+	}
+	direction := "ASC"
+	if opts.Descending {
+		direction = "DESC"
+	}
+	order := fmt.Sprintf(" ORDER BY %[1]s %[2]s, %[3]sid %[2]s", column, direction, prefix)
+	return where, order, args, nil
+}
+
+func appendLimitOffset(query string, args []interface{}, opts UserListOptions) (string, []interface{}) {
+	args = append([]interface{}{}, args...)
+	if opts.Limit > 0 {
+		args = append(args, opts.Limit)
+		query += fmt.Sprintf(" LIMIT $%d", len(args))
 	}
-	return users
+	if opts.Offset > 0 {
+		args = append(args, opts.Offset)
+		query += fmt.Sprintf(" OFFSET $%d", len(args))
+	}
+	return query, args
+}
+
+// escapeLikePattern escapes LIKE wildcards so search terms match literally.
+func escapeLikePattern(value string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(value)
 }
 
 func (r *postgresRepository) GetUser(id string) (models.User, bool) {
@@ -320,7 +477,7 @@ func (r *postgresRepository) GetUser(id string) (models.User, bool) {
 
 	var user models.User
 	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		row := conn.QueryRow(ctx, "SELECT id, display_name, email, roles, password_hash, self_signup, created_at FROM users WHERE id = $1", id)
+		row := conn.QueryRow(ctx, "SELECT id, display_name, email, roles, password_hash, self_signup, created_at, birth_date, age_confirmed_at, deactivated_at, username, username_changed_at, username_grace, max_concurrent_streams FROM users WHERE id = $1", id)
 		scanned, scanErr := scanUser(row)
 		if scanErr != nil {
 			return scanErr
@@ -343,17 +500,11 @@ func (r *postgresRepository) UpdateUser(id string, update UserUpdate) (models.Us
 	}
 
 	var updated models.User
-	updateErr := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
-		if err != nil {
-			return fmt.Errorf("begin update user tx: %w", err)
-		}
-		defer rollbackTx(ctx, tx)
-
-		row := tx.QueryRow(ctx, "SELECT id, display_name, email, roles, password_hash, self_signup, created_at FROM users WHERE id = $1 FOR UPDATE", id)
+	updateErr := r.withTx(txSpec{Name: "update user", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, "SELECT id, display_name, email, roles, password_hash, self_signup, created_at, birth_date, age_confirmed_at, deactivated_at, username, username_changed_at, username_grace, max_concurrent_streams FROM users WHERE id = $1 FOR UPDATE", id)
 		user, err := scanUser(row)
 		if errors.Is(err, pgx.ErrNoRows) {
-			return fmt.Errorf("user %s not found", id)
+			return notFound("user", id)
 		}
 		if err != nil {
 			return fmt.Errorf("load user %s: %w", id, err)
@@ -362,7 +513,7 @@ func (r *postgresRepository) UpdateUser(id string, update UserUpdate) (models.Us
 		if update.DisplayName != nil {
 			name := strings.TrimSpace(*update.DisplayName)
 			if name == "" {
-				return fmt.Errorf("displayName cannot be empty")
+				return invalid("displayName", "displayName cannot be empty")
 			}
 			user.DisplayName = name
 		}
@@ -370,7 +521,7 @@ func (r *postgresRepository) UpdateUser(id string, update UserUpdate) (models.Us
 		if update.Email != nil {
 			email := strings.TrimSpace(strings.ToLower(*update.Email))
 			if email == "" {
-				return fmt.Errorf("email cannot be empty")
+				return invalid("email", "email cannot be empty")
 			}
 			var existingID string
 			err = tx.QueryRow(ctx, "SELECT id FROM users WHERE email = $1 AND id <> $2", email, id).Scan(&existingID)
@@ -378,7 +529,7 @@ func (r *postgresRepository) UpdateUser(id string, update UserUpdate) (models.Us
 				return fmt.Errorf("check email uniqueness: %w", err)
 			}
 			if err == nil {
-				return fmt.Errorf("email %s already in use", email)
+				return conflict("email", "email %s already in use", email)
 			}
 			user.Email = email
 		}
@@ -390,13 +541,13 @@ func (r *postgresRepository) UpdateUser(id string, update UserUpdate) (models.Us
 			}
 		}
 
-		_, err = tx.Exec(ctx, "UPDATE users SET display_name = $1, email = $2, roles = $3 WHERE id = $4", user.DisplayName, user.Email, user.Roles, id)
-		if err != nil {
-			return fmt.Errorf("update user %s: %w", id, err)
+		if update.Deactivated != nil {
+			user.DeactivatedAt = applyDeactivation(user.DeactivatedAt, *update.Deactivated, time.Now().UTC())
 		}
 
-		if err := tx.Commit(ctx); err != nil {
-			return fmt.Errorf("commit update user: %w", err)
+		_, err = tx.Exec(ctx, "UPDATE users SET display_name = $1, email = $2, roles = $3, deactivated_at = $4 WHERE id = $5", user.DisplayName, user.Email, user.Roles, user.DeactivatedAt, id)
+		if err != nil {
+			return fmt.Errorf("update user %s: %w", id, err)
 		}
 
 		updated = user
@@ -409,6 +560,42 @@ func (r *postgresRepository) UpdateUser(id string, update UserUpdate) (models.Us
 	return updated, nil
 }
 
+func (r *postgresRepository) ConfirmUserBirthDate(id string, birthDate time.Time) (models.User, error) {
+	if r == nil || r.pool == nil {
+		return models.User{}, ErrPostgresUnavailable
+	}
+	now := time.Now().UTC()
+	date, err := normalizeBirthDate(birthDate, now)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	var user models.User
+	updateErr := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		row := conn.QueryRow(ctx, "UPDATE users SET birth_date = $1, age_confirmed_at = $2 WHERE id = $3 AND birth_date IS NULL RETURNING id, display_name, email, roles, password_hash, self_signup, created_at, birth_date, age_confirmed_at, deactivated_at, username, username_changed_at, username_grace, max_concurrent_streams", date, now, id)
+		scanned, err := scanUser(row)
+		if errors.Is(err, pgx.ErrNoRows) {
+			var exists bool
+			if err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)", id).Scan(&exists); err != nil {
+				return fmt.Errorf("load user %s: %w", id, err)
+			}
+			if !exists {
+				return notFound("user", id)
+			}
+			return ErrBirthDateAlreadySet
+		}
+		if err != nil {
+			return fmt.Errorf("confirm birth date: %w", err)
+		}
+		user = scanned
+		return nil
+	})
+	if updateErr != nil {
+		return models.User{}, updateErr
+	}
+	return user, nil
+}
+
 func (r *postgresRepository) SetUserPassword(id, password string) (models.User, error) {
 	if r == nil || r.pool == nil {
 		return models.User{}, ErrPostgresUnavailable
@@ -423,22 +610,21 @@ func (r *postgresRepository) SetUserPassword(id, password string) (models.User,
 	}
 
 	var user models.User
-	var roles []string
 	updateErr := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		row := conn.QueryRow(ctx, "UPDATE users SET password_hash = $1 WHERE id = $2 RETURNING id, display_name, email, roles, password_hash, self_signup, created_at", hashed, id)
-		if err := row.Scan(&user.ID, &user.DisplayName, &user.Email, &roles, &user.PasswordHash, &user.SelfSignup, &user.CreatedAt); err != nil {
+		row := conn.QueryRow(ctx, "UPDATE users SET password_hash = $1 WHERE id = $2 RETURNING id, display_name, email, roles, password_hash, self_signup, created_at, birth_date, age_confirmed_at, deactivated_at, username, username_changed_at, username_grace, max_concurrent_streams", hashed, id)
+		scanned, err := scanUser(row)
+		if err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
-				return fmt.Errorf("user %s not found", id)
+				return notFound("user", id)
 			}
 			return fmt.Errorf("update user password: %w", err)
 		}
+		user = scanned
 		return nil
 	})
 	if updateErr != nil {
 		return models.User{}, updateErr
 	}
-
-	user.Roles = roles
 	return user, nil
 }
 
@@ -447,28 +633,22 @@ func (r *postgresRepository) DeleteUser(id string) error {
 		return ErrPostgresUnavailable
 	}
 
-	deleteErr := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
-		if err != nil {
-			return fmt.Errorf("begin delete user tx: %w", err)
-		}
-		defer rollbackTx(ctx, tx)
-
+	deleteErr := r.withTx(txSpec{Name: "delete user", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
 		var userExists bool
 		if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)", id).Scan(&userExists); err != nil {
 			return fmt.Errorf("check user %s existence: %w", id, err)
 		}
 		if !userExists {
-			return fmt.Errorf("user %s not found", id)
+			return notFound("user", id)
 		}
 
 		var ownedChannelID string
-		err = tx.QueryRow(ctx, "SELECT id FROM channels WHERE owner_id = $1 LIMIT 1", id).Scan(&ownedChannelID)
+		err := tx.QueryRow(ctx, "SELECT id FROM channels WHERE owner_id = $1 LIMIT 1", id).Scan(&ownedChannelID)
 		if err != nil && !errors.Is(err, pgx.ErrNoRows) {
 			return fmt.Errorf("check owned channels: %w", err)
 		}
 		if err == nil {
-			return fmt.Errorf("user %s owns channel %s; transfer or delete the channel first", id, ownedChannelID)
+			return precondition(fmt.Sprintf("user %s owns channel %s; transfer or delete the channel first", id, ownedChannelID))
 		}
 
 		if _, err := tx.Exec(ctx, "UPDATE profiles SET top_friends = array_remove(top_friends, $1), updated_at = NOW() WHERE $1 = ANY(top_friends)", id); err != nil {
@@ -479,10 +659,6 @@ func (r *postgresRepository) DeleteUser(id string) error {
 			return fmt.Errorf("delete user %s: %w", id, err)
 		}
 
-		if err := tx.Commit(ctx); err != nil {
-			return fmt.Errorf("commit delete user: %w", err)
-		}
-
 		return nil
 	})
 	if deleteErr != nil {
@@ -492,67 +668,484 @@ func (r *postgresRepository) DeleteUser(id string) error {
 	return nil
 }
 
-func (r *postgresRepository) acquireContext() (context.Context, context.CancelFunc) {
-	if r == nil {
-		return context.Background(), func() {}
+func (r *postgresRepository) CreateAPIToken(params CreateAPITokenParams) (models.APIToken, string, error) {
+	if r == nil || r.pool == nil {
+		return models.APIToken{}, "", ErrPostgresUnavailable
 	}
-	if r.cfg.AcquireTimeout > 0 {
-		return context.WithTimeout(context.Background(), r.cfg.AcquireTimeout)
+	token, err := newAPITokenRecord(params, time.Now().UTC())
+	if err != nil {
+		return models.APIToken{}, "", err
 	}
-	return context.Background(), func() {}
+	secret, hashed, err := generateAPITokenSecret()
+	if err != nil {
+		return models.APIToken{}, "", err
+	}
+	id, err := generateID()
+	if err != nil {
+		return models.APIToken{}, "", err
+	}
+	token.ID = id
+	token.TokenHash = hashed
+
+	err = r.withTx(txSpec{Name: "create api token", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		if err := ensureUserExists(ctx, tx, token.UserID); err != nil {
+			return err
+		}
+
+		var expiresAt any
+		if token.ExpiresAt != nil {
+			expiresAt = *token.ExpiresAt
+		}
+		_, err = tx.Exec(ctx, "INSERT INTO api_tokens (id, user_id, name, scopes, token_hash, created_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6, $7)", token.ID, token.UserID, token.Name, token.Scopes, token.TokenHash, token.CreatedAt, expiresAt)
+		if err != nil {
+			return fmt.Errorf("insert api token: %w", err)
+		}
+
+		return nil
+	})
+	if err != nil {
+		return models.APIToken{}, "", err
+	}
+	return cloneAPIToken(token), secret, nil
 }
 
-func (r *postgresRepository) withConn(fn func(context.Context, *pgxpool.Conn) error) error {
+func (r *postgresRepository) ListAPITokens(userID string) ([]models.APIToken, error) {
 	if r == nil || r.pool == nil {
-		return ErrPostgresUnavailable
+		return nil, ErrPostgresUnavailable
 	}
-	ctx, cancel := r.acquireContext()
-	defer cancel()
-	conn, err := r.pool.Acquire(ctx)
+	tokens := make([]models.APIToken, 0)
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		var exists bool
+		if err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists); err != nil {
+			return fmt.Errorf("check user %s: %w", userID, err)
+		}
+		if !exists {
+			return notFound("user", userID)
+		}
+
+		rows, err := conn.Query(ctx, "SELECT id, user_id, name, scopes, token_hash, created_at, expires_at FROM api_tokens WHERE user_id = $1 ORDER BY created_at DESC, id", userID)
+		if err != nil {
+			return fmt.Errorf("list api tokens: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			token, err := scanAPIToken(rows)
+			if err != nil {
+				return fmt.Errorf("scan api token: %w", err)
+			}
+			tokens = append(tokens, token)
+		}
+		return rows.Err()
+	})
 	if err != nil {
-		return fmt.Errorf("acquire postgres connection: %w", err)
+		return nil, err
 	}
-	defer conn.Release()
-	return fn(ctx, conn)
+	return tokens, nil
 }
 
-func encodeDonationAddresses(addresses []models.CryptoAddress) ([]byte, error) {
-	if addresses == nil {
-		addresses = []models.CryptoAddress{}
+func (r *postgresRepository) RevokeAPIToken(userID, tokenID string) error {
+	if r == nil || r.pool == nil {
+		return ErrPostgresUnavailable
 	}
-	data, err := json.Marshal(addresses)
+	return r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		tag, err := conn.Exec(ctx, "DELETE FROM api_tokens WHERE id = $1 AND user_id = $2", tokenID, userID)
+		if err != nil {
+			return fmt.Errorf("delete api token %s: %w", tokenID, err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrAPITokenNotFound
+		}
+		return nil
+	})
+}
+
+func (r *postgresRepository) AuthenticateAPIToken(secret string) (models.APIToken, models.User, error) {
+	if r == nil || r.pool == nil {
+		return models.APIToken{}, models.User{}, ErrPostgresUnavailable
+	}
+	hashed, err := hashAPITokenSecret(secret)
 	if err != nil {
-		return nil, fmt.Errorf("encode donation addresses: %w", err)
+		return models.APIToken{}, models.User{}, err
 	}
-	return data, nil
-}
+	var (
+		token models.APIToken
+		user  models.User
+	)
+	err = r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		row := conn.QueryRow(ctx, "SELECT id, user_id, name, scopes, token_hash, created_at, expires_at FROM api_tokens WHERE token_hash = $1", hashed)
+		scanned, err := scanAPIToken(row)
+		if err != nil {
+			return err
+		}
+		token = scanned
 
-func decodeDonationAddresses(data []byte) ([]models.CryptoAddress, error) {
-	if len(data) == 0 {
-		return []models.CryptoAddress{}, nil
+		userRow := conn.QueryRow(ctx, "SELECT id, display_name, email, roles, password_hash, self_signup, created_at, birth_date, age_confirmed_at, deactivated_at, username, username_changed_at, username_grace, max_concurrent_streams FROM users WHERE id = $1", token.UserID)
+		user, err = scanUser(userRow)
+		return err
+	})
+	if errors.Is(err, pgx.ErrNoRows) {
+		return models.APIToken{}, models.User{}, ErrInvalidAPIToken
 	}
-	var addresses []models.CryptoAddress
-	if err := json.Unmarshal(data, &addresses); err != nil {
-		return nil, fmt.Errorf("decode donation addresses: %w", err)
+	if err != nil {
+		return models.APIToken{}, models.User{}, fmt.Errorf("authenticate api token: %w", err)
 	}
-	if addresses == nil {
-		addresses = []models.CryptoAddress{}
+	if apiTokenExpired(token, time.Now().UTC()) || user.Deactivated() {
+		return models.APIToken{}, models.User{}, ErrInvalidAPIToken
 	}
-	return addresses, nil
+	return token, user, nil
 }
 
-func encodeSocialLinks(links []models.SocialLink) ([]byte, error) {
-	if links == nil {
-		links = []models.SocialLink{}
+func scanAPIToken(row pgx.Row) (models.APIToken, error) {
+	var (
+		token     models.APIToken
+		scopes    []string
+		createdAt time.Time
+		expiresAt pgtype.Timestamptz
+	)
+	if err := row.Scan(&token.ID, &token.UserID, &token.Name, &scopes, &token.TokenHash, &createdAt, &expiresAt); err != nil {
+		return models.APIToken{}, err
 	}
-	data, err := json.Marshal(links)
-	if err != nil {
-		return nil, fmt.Errorf("encode social links: %w", err)
+	token.Scopes = append([]string{}, scopes...)
+	token.CreatedAt = createdAt.UTC()
+	if expiresAt.Valid {
+		expires := expiresAt.Time.UTC()
+		token.ExpiresAt = &expires
 	}
-	return data, nil
+	return token, nil
 }
 
-func decodeSocialLinks(data []byte) ([]models.SocialLink, error) {
+const notificationPreferenceColumns = "user_id, followed_live_in_app, followed_live_email, mentions_in_app, mentions_email, moderation_in_app, moderation_email, account_in_app, account_email, updated_at"
+
+func scanNotificationPreferences(row pgx.Row) (models.NotificationPreferences, error) {
+	var (
+		prefs     models.NotificationPreferences
+		updatedAt time.Time
+	)
+	if err := row.Scan(
+		&prefs.UserID,
+		&prefs.FollowedLive.InApp, &prefs.FollowedLive.Email,
+		&prefs.Mentions.InApp, &prefs.Mentions.Email,
+		&prefs.Moderation.InApp, &prefs.Moderation.Email,
+		&prefs.Account.InApp, &prefs.Account.Email,
+		&updatedAt,
+	); err != nil {
+		return models.NotificationPreferences{}, err
+	}
+	updatedAt = updatedAt.UTC()
+	prefs.UpdatedAt = &updatedAt
+	return prefs, nil
+}
+
+// rowQuerier is satisfied by both pooled connections and transactions.
+type rowQuerier interface {
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// loadNotificationPreferences reads the user's stored preferences, falling
+// back to the defaults, and fails when the user does not exist.
+func loadNotificationPreferences(ctx context.Context, q rowQuerier, userID string, forUpdate bool) (models.NotificationPreferences, error) {
+	query := "SELECT " + notificationPreferenceColumns + " FROM notification_preferences WHERE user_id = $1"
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+	prefs, err := scanNotificationPreferences(q.QueryRow(ctx, query, userID))
+	if err == nil {
+		return prefs, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return models.NotificationPreferences{}, fmt.Errorf("load notification preferences: %w", err)
+	}
+	var exists bool
+	if err := q.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists); err != nil {
+		return models.NotificationPreferences{}, fmt.Errorf("load user %s: %w", userID, err)
+	}
+	if !exists {
+		return models.NotificationPreferences{}, notFound("user", userID)
+	}
+	return models.DefaultNotificationPreferences(userID), nil
+}
+
+func (r *postgresRepository) GetNotificationPreferences(userID string) (models.NotificationPreferences, error) {
+	if r == nil || r.pool == nil {
+		return models.NotificationPreferences{}, ErrPostgresUnavailable
+	}
+	var prefs models.NotificationPreferences
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		loaded, err := loadNotificationPreferences(ctx, conn, userID, false)
+		if err != nil {
+			return err
+		}
+		prefs = loaded
+		return nil
+	})
+	if err != nil {
+		return models.NotificationPreferences{}, err
+	}
+	return prefs, nil
+}
+
+func (r *postgresRepository) UpdateNotificationPreferences(userID string, update NotificationPreferencesUpdate) (models.NotificationPreferences, error) {
+	if r == nil || r.pool == nil {
+		return models.NotificationPreferences{}, ErrPostgresUnavailable
+	}
+	var prefs models.NotificationPreferences
+	err := r.withTx(txSpec{Name: "update notification preferences", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		current, err := loadNotificationPreferences(ctx, tx, userID, true)
+		if err != nil {
+			return err
+		}
+		update.apply(&current)
+		row := tx.QueryRow(ctx, "INSERT INTO notification_preferences ("+notificationPreferenceColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10) "+
+			"ON CONFLICT (user_id) DO UPDATE SET followed_live_in_app = EXCLUDED.followed_live_in_app, followed_live_email = EXCLUDED.followed_live_email, "+
+			"mentions_in_app = EXCLUDED.mentions_in_app, mentions_email = EXCLUDED.mentions_email, moderation_in_app = EXCLUDED.moderation_in_app, "+
+			"moderation_email = EXCLUDED.moderation_email, account_in_app = EXCLUDED.account_in_app, account_email = EXCLUDED.account_email, updated_at = EXCLUDED.updated_at "+
+			"RETURNING "+notificationPreferenceColumns,
+			userID,
+			current.FollowedLive.InApp, current.FollowedLive.Email,
+			current.Mentions.InApp, current.Mentions.Email,
+			current.Moderation.InApp, current.Moderation.Email,
+			current.Account.InApp, current.Account.Email,
+			time.Now().UTC(),
+		)
+		saved, err := scanNotificationPreferences(row)
+		if err != nil {
+			return fmt.Errorf("save notification preferences: %w", err)
+		}
+		prefs = saved
+		return nil
+	})
+	if err != nil {
+		return models.NotificationPreferences{}, err
+	}
+	return prefs, nil
+}
+
+const playbackPreferenceColumns = "user_id, default_quality, prefer_low_latency, muted_autoplay, updated_at"
+
+func scanPlaybackPreferences(row pgx.Row) (models.PlaybackPreferences, error) {
+	var (
+		prefs     models.PlaybackPreferences
+		updatedAt time.Time
+	)
+	if err := row.Scan(&prefs.UserID, &prefs.DefaultQuality, &prefs.PreferLowLatency, &prefs.MutedAutoplay, &updatedAt); err != nil {
+		return models.PlaybackPreferences{}, err
+	}
+	updatedAt = updatedAt.UTC()
+	prefs.UpdatedAt = &updatedAt
+	return prefs, nil
+}
+
+// loadPlaybackPreferences reads the user's stored playback preferences,
+// falling back to the defaults, and fails when the user does not exist.
+func loadPlaybackPreferences(ctx context.Context, q rowQuerier, userID string, forUpdate bool) (models.PlaybackPreferences, error) {
+	query := "SELECT " + playbackPreferenceColumns + " FROM playback_preferences WHERE user_id = $1"
+	if forUpdate {
+		query += " FOR UPDATE"
+	}
+	prefs, err := scanPlaybackPreferences(q.QueryRow(ctx, query, userID))
+	if err == nil {
+		return prefs, nil
+	}
+	if !errors.Is(err, pgx.ErrNoRows) {
+		return models.PlaybackPreferences{}, fmt.Errorf("load playback preferences: %w", err)
+	}
+	var exists bool
+	if err := q.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists); err != nil {
+		return models.PlaybackPreferences{}, fmt.Errorf("load user %s: %w", userID, err)
+	}
+	if !exists {
+		return models.PlaybackPreferences{}, notFound("user", userID)
+	}
+	return models.DefaultPlaybackPreferences(userID), nil
+}
+
+func (r *postgresRepository) GetPlaybackPreferences(userID string) (models.PlaybackPreferences, error) {
+	if r == nil || r.pool == nil {
+		return models.PlaybackPreferences{}, ErrPostgresUnavailable
+	}
+	var prefs models.PlaybackPreferences
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		loaded, err := loadPlaybackPreferences(ctx, conn, userID, false)
+		if err != nil {
+			return err
+		}
+		prefs = loaded
+		return nil
+	})
+	if err != nil {
+		return models.PlaybackPreferences{}, err
+	}
+	return prefs, nil
+}
+
+func (r *postgresRepository) UpdatePlaybackPreferences(userID string, update PlaybackPreferencesUpdate) (models.PlaybackPreferences, error) {
+	if r == nil || r.pool == nil {
+		return models.PlaybackPreferences{}, ErrPostgresUnavailable
+	}
+	var prefs models.PlaybackPreferences
+	err := r.withTx(txSpec{Name: "update playback preferences", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		current, err := loadPlaybackPreferences(ctx, tx, userID, true)
+		if err != nil {
+			return err
+		}
+		if err := update.Apply(&current); err != nil {
+			return err
+		}
+		row := tx.QueryRow(ctx, "INSERT INTO playback_preferences ("+playbackPreferenceColumns+") VALUES ($1, $2, $3, $4, $5) "+
+			"ON CONFLICT (user_id) DO UPDATE SET default_quality = EXCLUDED.default_quality, prefer_low_latency = EXCLUDED.prefer_low_latency, "+
+			"muted_autoplay = EXCLUDED.muted_autoplay, updated_at = EXCLUDED.updated_at "+
+			"RETURNING "+playbackPreferenceColumns,
+			userID, current.DefaultQuality, current.PreferLowLatency, current.MutedAutoplay, time.Now().UTC(),
+		)
+		saved, err := scanPlaybackPreferences(row)
+		if err != nil {
+			return fmt.Errorf("save playback preferences: %w", err)
+		}
+		prefs = saved
+		return nil
+	})
+	if err != nil {
+		return models.PlaybackPreferences{}, err
+	}
+	return prefs, nil
+}
+
+func (r *postgresRepository) acquireContext() (context.Context, context.CancelFunc) {
+	if r == nil {
+		return context.Background(), func() {}
+	}
+	if r.cfg.AcquireTimeout > 0 {
+		return context.WithTimeout(context.Background(), r.cfg.AcquireTimeout)
+	}
+	return context.Background(), func() {}
+}
+
+func (r *postgresRepository) withConn(fn func(context.Context, *pgxpool.Conn) error) error {
+	if r == nil || r.pool == nil {
+		return ErrPostgresUnavailable
+	}
+	ctx, cancel := r.acquireContext()
+	defer cancel()
+	conn, err := r.acquirePrimaryConn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Release()
+	return fn(ctx, conn)
+}
+
+func encodeDonationAddresses(addresses []models.CryptoAddress) ([]byte, error) {
+	if addresses == nil {
+		addresses = []models.CryptoAddress{}
+	}
+	data, err := json.Marshal(addresses)
+	if err != nil {
+		return nil, fmt.Errorf("encode donation addresses: %w", err)
+	}
+	return data, nil
+}
+
+func decodeDonationAddresses(data []byte) ([]models.CryptoAddress, error) {
+	if len(data) == 0 {
+		return []models.CryptoAddress{}, nil
+	}
+	var addresses []models.CryptoAddress
+	if err := json.Unmarshal(data, &addresses); err != nil {
+		return nil, fmt.Errorf("decode donation addresses: %w", err)
+	}
+	if addresses == nil {
+		addresses = []models.CryptoAddress{}
+	}
+	return addresses, nil
+}
+
+// encodeStreamSettings returns nil for sessions without a snapshot so the
+// column stays NULL rather than holding a JSON null.
+func encodeStreamSettings(settings *models.StreamSettings) ([]byte, error) {
+	if settings == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(settings)
+	if err != nil {
+		return nil, fmt.Errorf("encode stream settings: %w", err)
+	}
+	return data, nil
+}
+
+func encodePlaybackEndpoints(endpoints []models.PlaybackEndpoint) ([]byte, error) {
+	if len(endpoints) == 0 {
+		return nil, nil
+	}
+	data, err := json.Marshal(endpoints)
+	if err != nil {
+		return nil, fmt.Errorf("encode playback endpoints: %w", err)
+	}
+	return data, nil
+}
+
+func decodePlaybackEndpoints(data []byte) ([]models.PlaybackEndpoint, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var endpoints []models.PlaybackEndpoint
+	if err := json.Unmarshal(data, &endpoints); err != nil {
+		return nil, fmt.Errorf("decode playback endpoints: %w", err)
+	}
+	if len(endpoints) == 0 {
+		return nil, nil
+	}
+	return endpoints, nil
+}
+
+func encodeTranscodeLimits(limits *models.TranscodeLimits) ([]byte, error) {
+	if limits == nil {
+		return nil, nil
+	}
+	data, err := json.Marshal(limits)
+	if err != nil {
+		return nil, fmt.Errorf("encode transcode limits: %w", err)
+	}
+	return data, nil
+}
+
+func decodeTranscodeLimits(data []byte) (*models.TranscodeLimits, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var limits models.TranscodeLimits
+	if err := json.Unmarshal(data, &limits); err != nil {
+		return nil, fmt.Errorf("decode transcode limits: %w", err)
+	}
+	if limits.IsZero() {
+		return nil, nil
+	}
+	return &limits, nil
+}
+
+func decodeStreamSettings(data []byte) (*models.StreamSettings, error) {
+	if len(data) == 0 {
+		return nil, nil
+	}
+	var settings models.StreamSettings
+	if err := json.Unmarshal(data, &settings); err != nil {
+		return nil, fmt.Errorf("decode stream settings: %w", err)
+	}
+	return &settings, nil
+}
+
+func encodeSocialLinks(links []models.SocialLink) ([]byte, error) {
+	if links == nil {
+		links = []models.SocialLink{}
+	}
+	data, err := json.Marshal(links)
+	if err != nil {
+		return nil, fmt.Errorf("encode social links: %w", err)
+	}
+	return data, nil
+}
+
+func decodeSocialLinks(data []byte) ([]models.SocialLink, error) {
 	if len(data) == 0 {
 		return []models.SocialLink{}, nil
 	}
@@ -580,9 +1173,24 @@ func (r *postgresRepository) loadStreamSession(ctx context.Context, id string) (
 		playbackURL     string
 		ingestEndpoints []string
 		ingestJobIDs    []string
+		previewURL      string
+		settingsBytes   []byte
+		endpointsBytes  []byte
+		receivingMedia  bool
+		confirmedAt     pgtype.Timestamptz
+		interruptedAt   pgtype.Timestamptz
+		publishSource   string
 	)
-	err := r.pool.QueryRow(ctx, "SELECT channel_id, started_at, ended_at, renditions, peak_concurrent, origin_url, playback_url, ingest_endpoints, ingest_job_ids FROM stream_sessions WHERE id = $1", id).
-		Scan(&channelID, &startedAt, &endedAt, &renditions, &peak, &originURL, &playbackURL, &ingestEndpoints, &ingestJobIDs)
+	err := r.pool.QueryRow(ctx, "SELECT channel_id, started_at, ended_at, renditions, peak_concurrent, origin_url, playback_url, ingest_endpoints, ingest_job_ids, preview_url, settings, playback_endpoints, receiving_media, media_confirmed_at, interrupted_at, publish_source FROM stream_sessions WHERE id = $1", id).
+		Scan(&channelID, &startedAt, &endedAt, &renditions, &peak, &originURL, &playbackURL, &ingestEndpoints, &ingestJobIDs, &previewURL, &settingsBytes, &endpointsBytes, &receivingMedia, &confirmedAt, &interruptedAt, &publishSource)
+	if err != nil {
+		return models.StreamSession{}, false
+	}
+	settings, err := decodeStreamSettings(settingsBytes)
+	if err != nil {
+		return models.StreamSession{}, false
+	}
+	playbackEndpoints, err := decodePlaybackEndpoints(endpointsBytes)
 	if err != nil {
 		return models.StreamSession{}, false
 	}
@@ -618,7 +1226,12 @@ func (r *postgresRepository) loadStreamSession(ctx context.Context, id string) (
 		IngestEndpoints:    append([]string{}, ingestEndpoints...),
 		IngestJobIDs:       append([]string{}, ingestJobIDs...),
 		RenditionManifests: manifests,
+		PreviewURL:         previewURL,
+		PlaybackEndpoints:  playbackEndpoints,
+		Settings:           settings,
+		PublishSource:      publishSource,
 	}
+	setSessionMedia(&session, receivingMedia, confirmedAt, interruptedAt)
 	if endedAt.Valid {
 		ts := endedAt.Time.UTC()
 		session.EndedAt = &ts
@@ -652,7 +1265,10 @@ func (r *postgresRepository) recordingDeadline(now time.Time, published bool) *t
 	return &deadline
 }
 
-func (r *postgresRepository) createRecording(session models.StreamSession, channel models.Channel, ended time.Time) (models.Recording, error) {
+// createRecording builds the recording of an ended session. outputBytes is
+// what the transcoder reported writing; artifacts uploaded to object storage
+// are added on top. The quota check happens when the recording is inserted.
+func (r *postgresRepository) createRecording(session models.StreamSession, channel models.Channel, ended time.Time, frame ingest.Frame, outputBytes int64) (models.Recording, error) {
 	recordingID, err := generateID()
 	if err != nil {
 		return models.Recording{}, err
@@ -675,6 +1291,7 @@ func (r *postgresRepository) createRecording(session models.StreamSession, chann
 	if session.PeakConcurrent > 0 {
 		metadata["peakConcurrent"] = strconv.Itoa(session.PeakConcurrent)
 	}
+	addStreamSettingsMetadata(metadata, session.Settings)
 	recording := models.Recording{
 		ID:              recordingID,
 		ChannelID:       channel.ID,
@@ -684,8 +1301,14 @@ func (r *postgresRepository) createRecording(session models.StreamSession, chann
 		PlaybackBaseURL: session.PlaybackURL,
 		Metadata:        metadata,
 		CreatedAt:       ended,
+		SizeBytes:       outputBytes,
+	}
+	published := channel.RecordingPolicy == models.RecordingPolicyAutoPublish
+	if published {
+		publishedAt := ended
+		recording.PublishedAt = &publishedAt
 	}
-	if deadline := r.recordingDeadline(ended, false); deadline != nil {
+	if deadline := r.recordingDeadline(ended, published); deadline != nil {
 		recording.RetainUntil = deadline
 	}
 	if len(session.RenditionManifests) > 0 {
@@ -695,13 +1318,13 @@ func (r *postgresRepository) createRecording(session models.StreamSession, chann
 		}
 		recording.Renditions = renditions
 	}
-	if err := r.populateRecordingArtifacts(&recording, session); err != nil {
+	if err := r.populateRecordingArtifacts(&recording, session, frame); err != nil {
 		return models.Recording{}, err
 	}
 	return recording, nil
 }
 
-func (r *postgresRepository) populateRecordingArtifacts(recording *models.Recording, session models.StreamSession) error {
+func (r *postgresRepository) populateRecordingArtifacts(recording *models.Recording, session models.StreamSession, frame ingest.Frame) error {
 	client := r.objectClient
 	if client == nil || !client.Enabled() {
 		return nil
@@ -734,6 +1357,7 @@ func (r *postgresRepository) populateRecordingArtifacts(recording *models.Record
 			if err != nil {
 				return fmt.Errorf("upload manifest %s: %w", manifest.Name, err)
 			}
+			recording.SizeBytes += int64(len(data))
 			if ref.Key != "" {
 				recording.Metadata[manifestMetadataKey(manifest.Name)] = ref.Key
 			}
@@ -743,37 +1367,7 @@ func (r *postgresRepository) populateRecordingArtifacts(recording *models.Record
 		}
 	}
 
-	thumbID, err := generateID()
-	if err != nil {
-		return fmt.Errorf("generate thumbnail id: %w", err)
-	}
-	thumbKey := buildObjectKey("recordings", recording.ID, "thumbnails", thumbID+".json")
-	thumbPayload := map[string]any{
-		"recordingId": recording.ID,
-		"sessionId":   recording.SessionID,
-		"createdAt":   createdAt,
-	}
-	thumbData, err := json.Marshal(thumbPayload)
-	if err != nil {
-		return fmt.Errorf("encode thumbnail payload: %w", err)
-	}
-	ctx, cancel := context.WithTimeout(context.Background(), r.objectStorage.requestTimeout())
-	ref, err := client.Upload(ctx, thumbKey, "application/json", thumbData)
-	cancel()
-	if err != nil {
-		return fmt.Errorf("upload thumbnail: %w", err)
-	}
-	if ref.Key != "" {
-		recording.Metadata[thumbnailMetadataKey(thumbID)] = ref.Key
-	}
-	thumbnail := models.RecordingThumbnail{
-		ID:          thumbID,
-		RecordingID: recording.ID,
-		URL:         ref.URL,
-		CreatedAt:   recording.CreatedAt,
-	}
-	recording.Thumbnails = append(recording.Thumbnails, thumbnail)
-	return nil
+	return attachRecordingThumbnail(client, r.objectStorage, recording, frame)
 }
 
 func (r *postgresRepository) insertRecording(ctx context.Context, tx pgx.Tx, recording models.Recording) error {
@@ -793,7 +1387,11 @@ func (r *postgresRepository) insertRecording(ctx context.Context, tx pgx.Tx, rec
 	if recording.RetainUntil != nil {
 		retainUntil = recording.RetainUntil
 	}
-	_, err = tx.Exec(ctx, "INSERT INTO recordings (id, channel_id, session_id, title, duration_seconds, playback_base_url, metadata, published_at, created_at, retain_until) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)",
+	chaptersJSON, err := encodeRecordingChapters(recording.Chapters)
+	if err != nil {
+		return err
+	}
+	_, err = tx.Exec(ctx, "INSERT INTO recordings (id, channel_id, session_id, title, duration_seconds, playback_base_url, metadata, published_at, created_at, retain_until, size_bytes, over_quota, chapters) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12, $13)",
 		recording.ID,
 		recording.ChannelID,
 		recording.SessionID,
@@ -804,6 +1402,9 @@ func (r *postgresRepository) insertRecording(ctx context.Context, tx pgx.Tx, rec
 		publishedAt,
 		recording.CreatedAt,
 		retainUntil,
+		recording.SizeBytes,
+		recording.OverQuota,
+		chaptersJSON,
 	)
 	if err != nil {
 		return fmt.Errorf("insert recording %s: %w", recording.ID, err)
@@ -876,7 +1477,9 @@ func (r *postgresRepository) retentionTime() time.Time {
 	return time.Now().UTC()
 }
 
-func (r *postgresRepository) runRecordingRetention(ctx context.Context) error {
+// PurgeExpiredRecordings deletes recordings, and their clips, whose
+// retention window has passed.
+func (r *postgresRepository) PurgeExpiredRecordings(ctx context.Context) error {
 	return r.purgeExpiredRecordings(ctx, r.retentionTime())
 }
 
@@ -884,7 +1487,7 @@ func (r *postgresRepository) purgeExpiredRecordings(ctx context.Context, now tim
 	if r == nil || r.pool == nil {
 		return ErrPostgresUnavailable
 	}
-	rows, err := r.pool.Query(ctx, "SELECT id, metadata FROM recordings WHERE retain_until IS NOT NULL AND retain_until <= $1", now)
+	rows, err := r.pool.Query(ctx, "SELECT id, channel_id, size_bytes, metadata FROM recordings WHERE retain_until IS NOT NULL AND retain_until <= $1", now)
 	if err != nil {
 		return err
 	}
@@ -892,9 +1495,13 @@ func (r *postgresRepository) purgeExpiredRecordings(ctx context.Context, now tim
 	ids := make([]string, 0)
 	recordings := make(map[string]models.Recording)
 	for rows.Next() {
-		var id string
-		var metadataBytes []byte
-		if err := rows.Scan(&id, &metadataBytes); err != nil {
+		var (
+			id            string
+			channelID     string
+			sizeBytes     int64
+			metadataBytes []byte
+		)
+		if err := rows.Scan(&id, &channelID, &sizeBytes, &metadataBytes); err != nil {
 			return err
 		}
 		meta := make(map[string]string)
@@ -903,7 +1510,7 @@ func (r *postgresRepository) purgeExpiredRecordings(ctx context.Context, now tim
 				return fmt.Errorf("decode recording metadata: %w", err)
 			}
 		}
-		recordings[id] = models.Recording{ID: id, Metadata: meta}
+		recordings[id] = models.Recording{ID: id, ChannelID: channelID, SizeBytes: sizeBytes, Metadata: meta}
 		ids = append(ids, id)
 	}
 	if err := rows.Err(); err != nil {
@@ -946,8 +1553,8 @@ func (r *postgresRepository) purgeExpiredRecordings(ctx context.Context, now tim
 		if failed {
 			continue
 		}
-		if _, err := r.pool.Exec(ctx, "DELETE FROM recordings WHERE id = $1", id); err != nil {
-			return fmt.Errorf("delete recording %s: %w", id, err)
+		if err := r.deleteRecordingRow(ctx, recording); err != nil {
+			return err
 		}
 	}
 	return nil
@@ -958,15 +1565,21 @@ func (r *postgresRepository) loadRecording(ctx context.Context, id string) (mode
 		channelID       string
 		sessionID       string
 		title           string
+		description     string
+		tags            []string
 		duration        int
 		playbackBaseURL string
 		metadataBytes   []byte
 		publishedAt     pgtype.Timestamptz
 		createdAt       time.Time
 		retainUntil     pgtype.Timestamptz
+		sizeBytes       int64
+		overQuota       bool
+		chaptersBytes   []byte
+		premiereAt      pgtype.Timestamptz
 	)
-	err := r.pool.QueryRow(ctx, "SELECT channel_id, session_id, title, duration_seconds, playback_base_url, metadata, published_at, created_at, retain_until FROM recordings WHERE id = $1", id).
-		Scan(&channelID, &sessionID, &title, &duration, &playbackBaseURL, &metadataBytes, &publishedAt, &createdAt, &retainUntil)
+	err := r.pool.QueryRow(ctx, "SELECT channel_id, session_id, title, description, tags, duration_seconds, playback_base_url, metadata, published_at, created_at, retain_until, size_bytes, over_quota, chapters, premiere_at FROM recordings WHERE id = $1", id).
+		Scan(&channelID, &sessionID, &title, &description, &tags, &duration, &playbackBaseURL, &metadataBytes, &publishedAt, &createdAt, &retainUntil, &sizeBytes, &overQuota, &chaptersBytes, &premiereAt)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return models.Recording{}, false, nil
 	}
@@ -979,15 +1592,26 @@ func (r *postgresRepository) loadRecording(ctx context.Context, id string) (mode
 			return models.Recording{}, false, fmt.Errorf("decode recording metadata: %w", err)
 		}
 	}
+	chapters, err := decodeRecordingChapters(chaptersBytes)
+	if err != nil {
+		return models.Recording{}, false, err
+	}
 	recording := models.Recording{
 		ID:              id,
 		ChannelID:       channelID,
 		SessionID:       sessionID,
 		Title:           title,
+		Description:     description,
 		DurationSeconds: duration,
 		PlaybackBaseURL: playbackBaseURL,
 		Metadata:        metadata,
 		CreatedAt:       createdAt.UTC(),
+		SizeBytes:       sizeBytes,
+		OverQuota:       overQuota,
+		Chapters:        chapters,
+	}
+	if len(tags) > 0 {
+		recording.Tags = tags
 	}
 	if publishedAt.Valid {
 		ts := publishedAt.Time.UTC()
@@ -997,6 +1621,10 @@ func (r *postgresRepository) loadRecording(ctx context.Context, id string) (mode
 		ts := retainUntil.Time.UTC()
 		recording.RetainUntil = &ts
 	}
+	if premiereAt.Valid {
+		ts := premiereAt.Time.UTC()
+		recording.PremiereAt = &ts
+	}
 	renditionsRows, err := r.pool.Query(ctx, "SELECT name, manifest_url, bitrate FROM recording_renditions WHERE recording_id = $1", id)
 	if err != nil {
 		return models.Recording{}, false, fmt.Errorf("load recording renditions: %w", err)
@@ -1076,6 +1704,7 @@ func (r *postgresRepository) loadUpload(ctx context.Context, id string) (models.
 		title         string
 		filename      string
 		sizeBytes     int64
+		contentHash   pgtype.Text
 		status        string
 		progress      int
 		recordingID   pgtype.Text
@@ -1085,9 +1714,11 @@ func (r *postgresRepository) loadUpload(ctx context.Context, id string) (models.
 		createdAt     time.Time
 		updatedAt     time.Time
 		completedAt   pgtype.Timestamptz
+		outputBytes   int64
+		stepsBytes    []byte
 	)
-	err := r.pool.QueryRow(ctx, "SELECT channel_id, title, filename, size_bytes, status, progress, recording_id, playback_url, metadata, error, created_at, updated_at, completed_at FROM uploads WHERE id = $1", id).
-		Scan(&channelID, &title, &filename, &sizeBytes, &status, &progress, &recordingID, &playbackURL, &metadataBytes, &errorText, &createdAt, &updatedAt, &completedAt)
+	err := r.pool.QueryRow(ctx, "SELECT channel_id, title, filename, size_bytes, content_hash, status, progress, recording_id, playback_url, metadata, error, created_at, updated_at, completed_at, output_bytes, steps FROM uploads WHERE id = $1", id).
+		Scan(&channelID, &title, &filename, &sizeBytes, &contentHash, &status, &progress, &recordingID, &playbackURL, &metadataBytes, &errorText, &createdAt, &updatedAt, &completedAt, &outputBytes, &stepsBytes)
 	if errors.Is(err, pgx.ErrNoRows) {
 		return models.Upload{}, false, nil
 	}
@@ -1100,17 +1731,23 @@ func (r *postgresRepository) loadUpload(ctx context.Context, id string) (models.
 			return models.Upload{}, false, fmt.Errorf("decode upload metadata: %w", err)
 		}
 	}
+	steps, err := decodeUploadSteps(stepsBytes)
+	if err != nil {
+		return models.Upload{}, false, err
+	}
 	upload := models.Upload{
-		ID:        id,
-		ChannelID: channelID,
-		Title:     title,
-		Filename:  filename,
-		SizeBytes: sizeBytes,
-		Status:    status,
-		Progress:  progress,
-		Metadata:  metadata,
-		CreatedAt: createdAt.UTC(),
-		UpdatedAt: updatedAt.UTC(),
+		Steps:       steps,
+		ID:          id,
+		ChannelID:   channelID,
+		Title:       title,
+		Filename:    filename,
+		SizeBytes:   sizeBytes,
+		OutputBytes: outputBytes,
+		Status:      status,
+		Progress:    progress,
+		Metadata:    metadata,
+		CreatedAt:   createdAt.UTC(),
+		UpdatedAt:   updatedAt.UTC(),
 	}
 	if recordingID.Valid {
 		value := strings.TrimSpace(recordingID.String)
@@ -1118,6 +1755,9 @@ func (r *postgresRepository) loadUpload(ctx context.Context, id string) (models.
 			upload.RecordingID = &value
 		}
 	}
+	if contentHash.Valid {
+		upload.ContentHash = contentHash.String
+	}
 	if playbackURL.Valid {
 		upload.PlaybackURL = playbackURL.String
 	}
@@ -1147,21 +1787,47 @@ func scanUser(row pgx.Row) (models.User, error) {
 		passwordHash           pgtype.Text
 		selfSignup             bool
 		createdAt              time.Time
+		birthDate              *time.Time
+		ageConfirmedAt         pgtype.Timestamptz
+		deactivatedAt          pgtype.Timestamptz
+		username               string
+		usernameChangedAt      pgtype.Timestamptz
+		usernameGrace          bool
+		maxConcurrentStreams   *int
 	)
-	if err := row.Scan(&id, &displayName, &email, &roles, &passwordHash, &selfSignup, &createdAt); err != nil {
+	if err := row.Scan(&id, &displayName, &email, &roles, &passwordHash, &selfSignup, &createdAt, &birthDate, &ageConfirmedAt, &deactivatedAt, &username, &usernameChangedAt, &usernameGrace, &maxConcurrentStreams); err != nil {
 		return models.User{}, err
 	}
 	user := models.User{
-		ID:          id,
-		DisplayName: displayName,
-		Email:       email,
-		Roles:       rolesFromDB(roles),
-		SelfSignup:  selfSignup,
-		CreatedAt:   createdAt.UTC(),
+		ID:            id,
+		DisplayName:   displayName,
+		Username:      username,
+		Email:         email,
+		Roles:         rolesFromDB(roles),
+		SelfSignup:    selfSignup,
+		CreatedAt:     createdAt.UTC(),
+		UsernameGrace: usernameGrace,
 	}
 	if passwordHash.Valid {
 		user.PasswordHash = passwordHash.String
 	}
+	if birthDate != nil {
+		born := birthDate.UTC()
+		user.BirthDate = &born
+	}
+	if ageConfirmedAt.Valid {
+		confirmed := ageConfirmedAt.Time.UTC()
+		user.AgeConfirmedAt = &confirmed
+	}
+	if deactivatedAt.Valid {
+		deactivated := deactivatedAt.Time.UTC()
+		user.DeactivatedAt = &deactivated
+	}
+	if usernameChangedAt.Valid {
+		changed := usernameChangedAt.Time.UTC()
+		user.UsernameChangedAt = &changed
+	}
+	user.MaxConcurrentStreams = maxConcurrentStreams
 	return user, nil
 }
 
@@ -1180,9 +1846,10 @@ func scanSubscriptionRow(row pgx.Row) (models.Subscription, error) {
 		cancelledReason   pgtype.Text
 		cancelledAt       pgtype.Timestamptz
 		externalReference pgtype.Text
+		gifterID          pgtype.Text
 	)
 	var amountMinor int64
-	if err := row.Scan(&sub.ID, &sub.ChannelID, &sub.UserID, &sub.Tier, &sub.Provider, &sub.Reference, &amountMinor, &sub.Currency, &sub.StartedAt, &sub.ExpiresAt, &sub.AutoRenew, &sub.Status, &cancelledBy, &cancelledReason, &cancelledAt, &externalReference); err != nil {
+	if err := row.Scan(&sub.ID, &sub.ChannelID, &sub.UserID, &sub.Tier, &sub.Provider, &sub.Reference, &amountMinor, &sub.Currency, &sub.StartedAt, &sub.ExpiresAt, &sub.AutoRenew, &sub.Status, &cancelledBy, &cancelledReason, &cancelledAt, &externalReference, &gifterID, &sub.IsGift); err != nil {
 		return models.Subscription{}, err
 	}
 	sub.Amount = models.NewMoneyFromMinorUnits(amountMinor)
@@ -1203,6 +1870,9 @@ func scanSubscriptionRow(row pgx.Row) (models.Subscription, error) {
 	if externalReference.Valid {
 		sub.ExternalReference = externalReference.String
 	}
+	if gifterID.Valid {
+		sub.GifterID = gifterID.String
+	}
 	return sub, nil
 }
 
@@ -1212,7 +1882,7 @@ func ensureUserExists(ctx context.Context, tx pgx.Tx, userID string) error {
 		return fmt.Errorf("check user %s: %w", userID, err)
 	}
 	if !exists {
-		return fmt.Errorf("user %s not found", userID)
+		return notFound("user", userID)
 	}
 	return nil
 }
@@ -1223,7 +1893,7 @@ func ensureChannelExists(ctx context.Context, tx pgx.Tx, channelID string) error
 		return fmt.Errorf("check channel %s: %w", channelID, err)
 	}
 	if !exists {
-		return fmt.Errorf("channel %s not found", channelID)
+		return notFound("channel", channelID)
 	}
 	return nil
 }
@@ -1232,18 +1902,16 @@ func (r *postgresRepository) UpsertProfile(userID string, update ProfileUpdate)
 	if r == nil || r.pool == nil {
 		return models.Profile{}, ErrPostgresUnavailable
 	}
+	if err := normalizeProfileImageUpdate(&update, r.objectStorage); err != nil {
+		return models.Profile{}, err
+	}
 	profile := models.Profile{}
-	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
-		if err != nil {
-			return fmt.Errorf("begin upsert profile tx: %w", err)
-		}
-		defer rollbackTx(ctx, tx)
-
+	var previous models.Profile
+	err := r.withTx(txSpec{Name: "upsert profile", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
 		var userCreatedAt time.Time
 		if err := tx.QueryRow(ctx, "SELECT created_at FROM users WHERE id = $1", userID).Scan(&userCreatedAt); err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
-				return fmt.Errorf("user %s not found", userID)
+				return notFound("user", userID)
 			}
 			return fmt.Errorf("load user %s: %w", userID, err)
 		}
@@ -1297,10 +1965,14 @@ func (r *postgresRepository) UpsertProfile(userID string, update ProfileUpdate)
 				if err != nil {
 					return fmt.Errorf("decode donation addresses: %w", err)
 				}
+				if err := openDonationAddresses(r.secrets, userID, decoded); err != nil {
+					return err
+				}
 				profile.DonationAddresses = decoded
 			}
 			profile.CreatedAt = createdAt.UTC()
 			profile.UpdatedAt = updatedAt.UTC()
+			previous = profile
 		}
 
 		now := time.Now().UTC()
@@ -1329,13 +2001,13 @@ func (r *postgresRepository) UpsertProfile(userID string, update ProfileUpdate)
 				var ownerID string
 				err := tx.QueryRow(ctx, "SELECT owner_id FROM channels WHERE id = $1", trimmed).Scan(&ownerID)
 				if errors.Is(err, pgx.ErrNoRows) {
-					return fmt.Errorf("featured channel %s not found", trimmed)
+					return notFound("featured channel", trimmed)
 				}
 				if err != nil {
 					return fmt.Errorf("load featured channel %s: %w", trimmed, err)
 				}
 				if ownerID != userID {
-					return errors.New("featured channel must belong to profile owner")
+					return invalid("featuredChannelId", "featured channel must belong to profile owner")
 				}
 				id := trimmed
 				profile.FeaturedChannelID = &id
@@ -1343,20 +2015,20 @@ func (r *postgresRepository) UpsertProfile(userID string, update ProfileUpdate)
 		}
 		if update.TopFriends != nil {
 			if len(*update.TopFriends) > 8 {
-				return errors.New("top friends cannot exceed eight entries")
+				return invalid("topFriends", "top friends cannot exceed eight entries")
 			}
 			seen := make(map[string]struct{}, len(*update.TopFriends))
 			ordered := make([]string, 0, len(*update.TopFriends))
 			for _, friendID := range *update.TopFriends {
 				trimmed := strings.TrimSpace(friendID)
 				if trimmed == "" {
-					return errors.New("top friends must reference valid users")
+					return invalid("topFriends", "top friends must reference valid users")
 				}
 				if trimmed == userID {
-					return errors.New("cannot add profile owner as a top friend")
+					return invalid("topFriends", "cannot add profile owner as a top friend")
 				}
 				if _, exists := seen[trimmed]; exists {
-					return errors.New("duplicate user in top friends list")
+					return invalid("topFriends", "duplicate user in top friends list")
 				}
 				seen[trimmed] = struct{}{}
 				ordered = append(ordered, trimmed)
@@ -1380,20 +2052,16 @@ func (r *postgresRepository) UpsertProfile(userID string, update ProfileUpdate)
 				}
 				for _, id := range ordered {
 					if _, ok := found[id]; !ok {
-						return fmt.Errorf("top friend %s not found", id)
+						return notFound("top friend", id)
 					}
 				}
 			}
 			profile.TopFriends = ordered
 		}
 		if update.DonationAddresses != nil {
-			addresses := make([]models.CryptoAddress, 0, len(*update.DonationAddresses))
-			for _, addr := range *update.DonationAddresses {
-				normalized, err := NormalizeDonationAddress(addr)
-				if err != nil {
-					return err
-				}
-				addresses = append(addresses, normalized)
+			addresses, err := NormalizeDonationAddresses(profile.DonationAddresses, *update.DonationAddresses)
+			if err != nil {
+				return err
 			}
 			profile.DonationAddresses = addresses
 		}
@@ -1403,11 +2071,15 @@ func (r *postgresRepository) UpsertProfile(userID string, update ProfileUpdate)
 			profile.CreatedAt = now
 		}
 
-		socialLinksPayload, err = encodeSocialLinks(profile.SocialLinks)
+		socialLinksPayload, err := encodeSocialLinks(profile.SocialLinks)
+		if err != nil {
+			return err
+		}
+		sealedAddresses, err := sealDonationAddresses(r.secrets, userID, profile.DonationAddresses)
 		if err != nil {
 			return err
 		}
-		donationPayload, err := encodeDonationAddresses(profile.DonationAddresses)
+		donationPayload, err := encodeDonationAddresses(sealedAddresses)
 		if err != nil {
 			return err
 		}
@@ -1449,10 +2121,6 @@ RETURNING created_at, updated_at`,
 			return fmt.Errorf("upsert profile %s: %w", userID, err)
 		}
 
-		if err := tx.Commit(ctx); err != nil {
-			return fmt.Errorf("commit upsert profile: %w", err)
-		}
-
 		profile.CreatedAt = insertedCreatedAt.UTC()
 		profile.UpdatedAt = insertedUpdatedAt.UTC()
 		if profile.TopFriends == nil {
@@ -1466,20 +2134,81 @@ RETURNING created_at, updated_at`,
 	if err != nil {
 		return models.Profile{}, err
 	}
+	deleteReplacedProfileImages(r.objectClient, r.objectStorage, previous, profile)
 	return profile, nil
 }
 
-func (r *postgresRepository) GetProfile(userID string) (models.Profile, bool) {
+// VerifyDonationAddress records that the owner of userID's profile proved
+// control of its donation address of currency at verifiedAt.
+func (r *postgresRepository) VerifyDonationAddress(userID string, currency models.CryptoCurrency, address string, verifiedAt time.Time) (models.Profile, error) {
 	if r == nil || r.pool == nil {
-		return models.Profile{}, false
+		return models.Profile{}, ErrPostgresUnavailable
 	}
-	var (
-		profile models.Profile
-		found   bool
-		ok      bool
-		loadErr error
-	)
-	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+	err := r.withTx(txSpec{Name: "verify donation address", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		var payload []byte
+		if err := tx.QueryRow(ctx, "SELECT donation_addresses FROM profiles WHERE user_id = $1 FOR UPDATE", userID).Scan(&payload); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrDonationAddressNotFound
+			}
+			return fmt.Errorf("load donation addresses of %s: %w", userID, err)
+		}
+		var addresses []models.CryptoAddress
+		if len(payload) > 0 {
+			decoded, err := decodeDonationAddresses(payload)
+			if err != nil {
+				return fmt.Errorf("decode donation addresses: %w", err)
+			}
+			if err := openDonationAddresses(r.secrets, userID, decoded); err != nil {
+				return err
+			}
+			addresses = decoded
+		}
+		if err := markDonationAddressVerified(addresses, currency, address, verifiedAt); err != nil {
+			return err
+		}
+		sealed, err := sealDonationAddresses(r.secrets, userID, addresses)
+		if err != nil {
+			return err
+		}
+		encoded, err := encodeDonationAddresses(sealed)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, "UPDATE profiles SET donation_addresses = $2 WHERE user_id = $1", userID, encoded); err != nil {
+			return fmt.Errorf("verify donation address of %s: %w", userID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Profile{}, err
+	}
+	profile, ok := r.GetProfile(userID)
+	if !ok {
+		return models.Profile{}, ErrDonationAddressNotFound
+	}
+	return profile, nil
+}
+
+// SetProfileImage stores data as the user's avatar or banner in object
+// storage and writes its public URL to the profile.
+func (r *postgresRepository) SetProfileImage(userID string, kind ProfileImageKind, data []byte) (models.Profile, error) {
+	if r == nil || r.pool == nil {
+		return models.Profile{}, ErrPostgresUnavailable
+	}
+	return setProfileImage(r, r.objectClient, r.objectStorage, userID, kind, data)
+}
+
+func (r *postgresRepository) GetProfile(userID string) (models.Profile, bool) {
+	if r == nil || r.pool == nil {
+		return models.Profile{}, false
+	}
+	var (
+		profile models.Profile
+		found   bool
+		ok      bool
+		loadErr error
+	)
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
 		var (
 			bio                      string
 			avatar, banner, featured pgtype.Text
@@ -1550,6 +2279,10 @@ func (r *postgresRepository) GetProfile(userID string) (models.Profile, bool) {
 					loadErr = err
 					return nil
 				}
+				if err := openDonationAddresses(r.secrets, userID, addresses); err != nil {
+					loadErr = err
+					return nil
+				}
 				profile.DonationAddresses = addresses
 			} else {
 				profile.DonationAddresses = []models.CryptoAddress{}
@@ -1580,102 +2313,159 @@ func (r *postgresRepository) GetProfile(userID string) (models.Profile, bool) {
 	return profile, found
 }
 
-func (r *postgresRepository) ListProfiles() []models.Profile {
+// ListProfiles reads from a replica when one is configured; a profile edited
+// a moment ago may show its previous version.
+func (r *postgresRepository) ListProfiles() ([]models.Profile, error) {
 	if r == nil || r.pool == nil {
-		return nil
+		return nil, ErrPostgresUnavailable
 	}
 	profiles := make([]models.Profile, 0)
-	var queryErr error
-	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		rows, err := conn.Query(ctx, "SELECT user_id, bio, avatar_url, banner_url, featured_channel_id, top_friends, social_links, donation_addresses, created_at, updated_at FROM profiles ORDER BY created_at ASC")
+	err := r.withReadConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		rows, err := conn.Query(ctx, "SELECT "+profileColumns("")+" FROM profiles ORDER BY created_at ASC, user_id ASC")
 		if err != nil {
-			queryErr = err
-			return nil
+			return fmt.Errorf("list profiles: %w", err)
 		}
 		defer rows.Close()
 
 		for rows.Next() {
-			var (
-				userID                   string
-				bio                      string
-				avatar, banner, featured pgtype.Text
-				topFriends               []string
-				socialLinksPayload       []byte
-				donationPayload          []byte
-				createdAt, updatedAt     time.Time
-			)
-			if err := rows.Scan(&userID, &bio, &avatar, &banner, &featured, &topFriends, &socialLinksPayload, &donationPayload, &createdAt, &updatedAt); err != nil {
-				queryErr = err
-				return nil
-			}
-			profile := models.Profile{
-				UserID:      userID,
-				Bio:         bio,
-				CreatedAt:   createdAt.UTC(),
-				UpdatedAt:   updatedAt.UTC(),
-				TopFriends:  []string{},
-				SocialLinks: []models.SocialLink{},
-			}
-			if avatar.Valid {
-				profile.AvatarURL = avatar.String
-			}
-			if banner.Valid {
-				profile.BannerURL = banner.String
-			}
-			if featured.Valid {
-				id := featured.String
-				profile.FeaturedChannelID = &id
-			}
-			if len(socialLinksPayload) > 0 {
-				links, err := decodeSocialLinks(socialLinksPayload)
-				if err != nil {
-					queryErr = err
-					return nil
-				}
-				profile.SocialLinks = links
-			}
-			if len(topFriends) > 0 {
-				profile.TopFriends = append([]string{}, topFriends...)
-			}
-			if len(donationPayload) > 0 {
-				addresses, err := decodeDonationAddresses(donationPayload)
-				if err != nil {
-					queryErr = err
-					return nil
-				}
-				profile.DonationAddresses = addresses
-			} else {
-				profile.DonationAddresses = []models.CryptoAddress{}
-			}
-			if profile.SocialLinks == nil {
-				profile.SocialLinks = []models.SocialLink{}
-			}
-			if profile.TopFriends == nil {
-				profile.TopFriends = []string{}
+			profile, err := r.scanProfile(rows)
+			if err != nil {
+				return err
 			}
 			profiles = append(profiles, profile)
 		}
-		if err := rows.Err(); err != nil {
-			queryErr = err
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return profiles, nil
+}
+
+// ListProfilesPage pages profiles joined to their owners so search, role
+// filters, and name or email ordering run in Postgres. Like ListProfiles it
+// reads from a replica when one is configured.
+func (r *postgresRepository) ListProfilesPage(opts UserListOptions) (ProfilePage, error) {
+	if r == nil || r.pool == nil {
+		return ProfilePage{}, ErrPostgresUnavailable
+	}
+	where, order, args, err := userListClauses(opts, "u.")
+	if err != nil {
+		return ProfilePage{}, err
+	}
+	page := ProfilePage{Profiles: make([]models.Profile, 0)}
+	err = r.withReadConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		from := " FROM profiles p JOIN users u ON u.id = p.user_id" + where
+		if err := conn.QueryRow(ctx, "SELECT COUNT(*)"+from, args...).Scan(&page.Total); err != nil {
+			return err
+		}
+		if page.Total == 0 {
 			return nil
 		}
-		return nil
+		query, queryArgs := appendLimitOffset("SELECT "+profileColumns("p.")+from+order, args, opts)
+		rows, err := conn.Query(ctx, query, queryArgs...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			profile, err := r.scanProfile(rows)
+			if err != nil {
+				return err
+			}
+			page.Profiles = append(page.Profiles, profile)
+		}
+		return rows.Err()
 	})
-	if err != nil || queryErr != nil {
-		return nil
+	if err != nil {
+		return ProfilePage{}, fmt.Errorf("list profiles page: %w", err)
+	}
+	return page, nil
+}
+
+func profileColumns(prefix string) string {
+	columns := []string{"user_id", "bio", "avatar_url", "banner_url", "featured_channel_id", "top_friends", "social_links", "donation_addresses", "created_at", "updated_at"}
+	for i, column := range columns {
+		columns[i] = prefix + column
+	}
+	return strings.Join(columns, ", ")
+}
+
+// scanProfile reads a row selected with profileColumns, opening its donation
+// addresses.
+func (r *postgresRepository) scanProfile(row pgx.Row) (models.Profile, error) {
+	var (
+		userID                   string
+		bio                      string
+		avatar, banner, featured pgtype.Text
+		topFriends               []string
+		socialLinksPayload       []byte
+		donationPayload          []byte
+		createdAt, updatedAt     time.Time
+	)
+	if err := row.Scan(&userID, &bio, &avatar, &banner, &featured, &topFriends, &socialLinksPayload, &donationPayload, &createdAt, &updatedAt); err != nil {
+		return models.Profile{}, err
+	}
+	profile := models.Profile{
+		UserID:            userID,
+		Bio:               bio,
+		CreatedAt:         createdAt.UTC(),
+		UpdatedAt:         updatedAt.UTC(),
+		TopFriends:        []string{},
+		SocialLinks:       []models.SocialLink{},
+		DonationAddresses: []models.CryptoAddress{},
+	}
+	if avatar.Valid {
+		profile.AvatarURL = avatar.String
+	}
+	if banner.Valid {
+		profile.BannerURL = banner.String
+	}
+	if featured.Valid {
+		id := featured.String
+		profile.FeaturedChannelID = &id
+	}
+	if len(socialLinksPayload) > 0 {
+		links, err := decodeSocialLinks(socialLinksPayload)
+		if err != nil {
+			return models.Profile{}, err
+		}
+		if links != nil {
+			profile.SocialLinks = links
+		}
+	}
+	if len(topFriends) > 0 {
+		profile.TopFriends = append([]string{}, topFriends...)
+	}
+	if len(donationPayload) > 0 {
+		addresses, err := decodeDonationAddresses(donationPayload)
+		if err != nil {
+			return models.Profile{}, err
+		}
+		if err := openDonationAddresses(r.secrets, userID, addresses); err != nil {
+			return models.Profile{}, err
+		}
+		if addresses != nil {
+			profile.DonationAddresses = addresses
+		}
 	}
-	return profiles
+	return profile, nil
 }
+
 func (r *postgresRepository) CreateChannel(ownerID, title, category string, tags []string) (models.Channel, error) {
 	if r == nil || r.pool == nil {
 		return models.Channel{}, ErrPostgresUnavailable
 	}
 	if strings.TrimSpace(ownerID) == "" {
-		return models.Channel{}, fmt.Errorf("owner %s not found", ownerID)
+		return models.Channel{}, notFound("owner", ownerID)
 	}
 	trimmedTitle := strings.TrimSpace(title)
 	if trimmedTitle == "" {
-		return models.Channel{}, errors.New("title is required")
+		return models.Channel{}, invalid("title", "title is required")
+	}
+	trimmedCategory, err := normalizeChannelCategory(category)
+	if err != nil {
+		return models.Channel{}, err
 	}
 
 	var (
@@ -1683,41 +2473,42 @@ func (r *postgresRepository) CreateChannel(ownerID, title, category string, tags
 		insertedCreatedAt time.Time
 		insertedUpdatedAt time.Time
 		streamKey         string
+		streamKeyHash     string
+		streamKeyHint     string
 		id                string
+		slug              string
 		normalizedTags    []string
-		trimmedCategory   string
 	)
-	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
-		if err != nil {
-			return fmt.Errorf("begin create channel tx: %w", err)
-		}
-		defer rollbackTx(ctx, tx)
-
+	err = r.withTx(txSpec{Name: "create channel", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
 		var exists bool
 		if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)", ownerID).Scan(&exists); err != nil {
 			return fmt.Errorf("check owner %s: %w", ownerID, err)
 		}
 		if !exists {
-			return fmt.Errorf("owner %s not found", ownerID)
+			return notFound("owner", ownerID)
 		}
 
 		id, err = generateID()
 		if err != nil {
 			return err
 		}
-		streamKey, err = generateStreamKey()
+		streamKey, streamKeyHash, streamKeyHint, err = newChannelStreamKey()
+		if err != nil {
+			return err
+		}
+		slug, err = deriveChannelSlug(trimmedTitle, postgresChannelSlugTaken(ctx, tx, ""))
 		if err != nil {
 			return err
 		}
 		normalizedTags = normalizeTags(tags)
-		trimmedCategory = strings.TrimSpace(category)
 		now := time.Now().UTC()
 
-		err = tx.QueryRow(ctx, "INSERT INTO channels (id, owner_id, stream_key, title, category, tags, live_state, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, 'offline', $7, $8) RETURNING created_at, updated_at",
+		err = tx.QueryRow(ctx, "INSERT INTO channels (id, owner_id, slug, stream_key_hash, stream_key_hint, title, category, tags, live_state, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, 'offline', $9, $10) RETURNING created_at, updated_at",
 			id,
 			ownerID,
-			streamKey,
+			slug,
+			streamKeyHash,
+			streamKeyHint,
 			trimmedTitle,
 			trimmedCategory,
 			normalizedTags,
@@ -1727,9 +2518,6 @@ func (r *postgresRepository) CreateChannel(ownerID, title, category string, tags
 		if err != nil {
 			return fmt.Errorf("insert channel: %w", err)
 		}
-		if err := tx.Commit(ctx); err != nil {
-			return fmt.Errorf("commit create channel: %w", err)
-		}
 		return nil
 	})
 	if err != nil {
@@ -1737,15 +2525,19 @@ func (r *postgresRepository) CreateChannel(ownerID, title, category string, tags
 	}
 
 	channel = models.Channel{
-		ID:        id,
-		OwnerID:   ownerID,
-		StreamKey: streamKey,
-		Title:     trimmedTitle,
-		Category:  trimmedCategory,
-		Tags:      normalizedTags,
-		LiveState: "offline",
-		CreatedAt: insertedCreatedAt.UTC(),
-		UpdatedAt: insertedUpdatedAt.UTC(),
+		ID:              id,
+		OwnerID:         ownerID,
+		Slug:            slug,
+		StreamKey:       streamKey,
+		StreamKeyHash:   streamKeyHash,
+		StreamKeyHint:   streamKeyHint,
+		Title:           trimmedTitle,
+		Category:        trimmedCategory,
+		Tags:            normalizedTags,
+		LiveState:       "offline",
+		CreatedAt:       insertedCreatedAt.UTC(),
+		UpdatedAt:       insertedUpdatedAt.UTC(),
+		RecordingPolicy: models.RecordingPolicyManual,
 	}
 	return channel, nil
 }
@@ -1755,38 +2547,59 @@ func (r *postgresRepository) UpdateChannel(id string, update ChannelUpdate) (mod
 		return models.Channel{}, ErrPostgresUnavailable
 	}
 	var channel models.Channel
-	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
-		if err != nil {
-			return fmt.Errorf("begin update channel tx: %w", err)
-		}
-		defer rollbackTx(ctx, tx)
-
+	err := r.withTx(txSpec{Name: "update channel", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
 		var (
-			channelID, ownerID, streamKey, title string
-			category                             pgtype.Text
-			tags                                 []string
-			liveState                            string
-			currentSession                       pgtype.Text
-			createdAt, updatedAt                 time.Time
+			channelID, ownerID, streamKeyHash, streamKeyHint, title string
+			category                                                pgtype.Text
+			tags                                                    []string
+			liveState                                               string
+			currentSession                                          pgtype.Text
+			createdAt, updatedAt                                    time.Time
+			playbackRestriction                                     string
+			playbackPreviews                                        bool
+			recordingPolicy                                         string
+			matureContent                                           bool
+			transcodeLimits                                         []byte
+			startingSince                                           pgtype.Timestamptz
+			chatMaxCapsPercent                                      int
+			chatRestrictLinks                                       bool
+			chatSubscribersOnly                                     bool
+			chatWelcomeMessage                                      string
+			language                                                string
+			contentWarnings                                         []string
+			slug                                                    string
+			slugChangedAt                                           pgtype.Timestamptz
 		)
-		row := tx.QueryRow(ctx, "SELECT id, owner_id, stream_key, title, category, tags, live_state, current_session_id, created_at, updated_at FROM channels WHERE id = $1 FOR UPDATE", id)
-		if err := row.Scan(&channelID, &ownerID, &streamKey, &title, &category, &tags, &liveState, &currentSession, &createdAt, &updatedAt); err != nil {
+		row := tx.QueryRow(ctx, "SELECT id, owner_id, stream_key_hash, stream_key_hint, title, category, tags, live_state, current_session_id, created_at, updated_at, playback_restriction, playback_previews, recording_policy, mature_content, transcode_limits, starting_since, chat_max_caps_percent, chat_restrict_links, chat_subscribers_only, chat_welcome_message, language, content_warnings, slug, slug_changed_at FROM channels WHERE id = $1 FOR UPDATE", id)
+		if err := row.Scan(&channelID, &ownerID, &streamKeyHash, &streamKeyHint, &title, &category, &tags, &liveState, &currentSession, &createdAt, &updatedAt, &playbackRestriction, &playbackPreviews, &recordingPolicy, &matureContent, &transcodeLimits, &startingSince, &chatMaxCapsPercent, &chatRestrictLinks, &chatSubscribersOnly, &chatWelcomeMessage, &language, &contentWarnings, &slug, &slugChangedAt); err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
-				return fmt.Errorf("channel %s not found", id)
+				return notFound("channel", id)
 			}
 			return fmt.Errorf("load channel %s: %w", id, err)
 		}
 
 		channel = models.Channel{
-			ID:        channelID,
-			OwnerID:   ownerID,
-			StreamKey: streamKey,
-			Title:     title,
-			Tags:      append([]string{}, tags...),
-			LiveState: liveState,
-			CreatedAt: createdAt.UTC(),
-			UpdatedAt: updatedAt.UTC(),
+			ID:                  channelID,
+			OwnerID:             ownerID,
+			StreamKeyHash:       streamKeyHash,
+			StreamKeyHint:       streamKeyHint,
+			Title:               title,
+			Tags:                append([]string{}, tags...),
+			LiveState:           liveState,
+			CreatedAt:           createdAt.UTC(),
+			UpdatedAt:           updatedAt.UTC(),
+			PlaybackRestriction: playbackRestriction,
+			PlaybackPreviews:    playbackPreviews,
+			RecordingPolicy:     recordingPolicy,
+			MatureContent:       matureContent,
+			ChatMaxCapsPercent:  chatMaxCapsPercent,
+			ChatRestrictLinks:   chatRestrictLinks,
+			ChatSubscribersOnly: chatSubscribersOnly,
+			ChatWelcomeMessage:  chatWelcomeMessage,
+			Language:            language,
+			ContentWarnings:     scannedContentWarnings(contentWarnings),
+			Slug:                slug,
+			SlugChangedAt:       scannedSlugChangedAt(slugChangedAt),
 		}
 		if category.Valid {
 			channel.Category = category.String
@@ -1795,16 +2608,29 @@ func (r *postgresRepository) UpdateChannel(id string, update ChannelUpdate) (mod
 			id := currentSession.String
 			channel.CurrentSessionID = &id
 		}
+		limits, err := decodeTranscodeLimits(transcodeLimits)
+		if err != nil {
+			return err
+		}
+		channel.TranscodeLimits = limits
+		if startingSince.Valid {
+			ts := startingSince.Time.UTC()
+			channel.StartingSince = &ts
+		}
 
 		if update.Title != nil {
 			trimmed := strings.TrimSpace(*update.Title)
 			if trimmed == "" {
-				return errors.New("title cannot be empty")
+				return invalid("title", "title cannot be empty")
 			}
 			channel.Title = trimmed
 		}
 		if update.Category != nil {
-			channel.Category = strings.TrimSpace(*update.Category)
+			category, err := normalizeChannelCategory(*update.Category)
+			if err != nil {
+				return err
+			}
+			channel.Category = category
 		}
 		if update.Tags != nil {
 			channel.Tags = normalizeTags(*update.Tags)
@@ -1813,27 +2639,101 @@ func (r *postgresRepository) UpdateChannel(id string, update ChannelUpdate) (mod
 			state := strings.ToLower(strings.TrimSpace(*update.LiveState))
 			switch state {
 			case "offline", "live", "starting", "ended":
-				channel.LiveState = state
+				setLiveState(&channel, state, time.Now().UTC())
 			default:
-				return fmt.Errorf("invalid liveState %s", state)
+				return invalid("liveState", "invalid liveState %s", state)
+			}
+		}
+		if update.PlaybackRestriction != nil {
+			restriction, err := normalizePlaybackRestriction(*update.PlaybackRestriction)
+			if err != nil {
+				return err
+			}
+			channel.PlaybackRestriction = restriction
+		}
+		if update.PlaybackPreviews != nil {
+			channel.PlaybackPreviews = *update.PlaybackPreviews
+		}
+		if update.RecordingPolicy != nil {
+			policy, err := normalizeRecordingPolicy(*update.RecordingPolicy)
+			if err != nil {
+				return err
+			}
+			channel.RecordingPolicy = policy
+		}
+		if update.MatureContent != nil {
+			channel.MatureContent = *update.MatureContent
+		}
+		if update.Language != nil {
+			language, err := normalizeChannelLanguage(*update.Language)
+			if err != nil {
+				return err
+			}
+			channel.Language = language
+		}
+		if update.ContentWarnings != nil {
+			warnings, err := normalizeContentWarnings(*update.ContentWarnings)
+			if err != nil {
+				return err
+			}
+			channel.ContentWarnings = warnings
+		}
+		if update.TranscodeLimits != nil {
+			limits, err := normalizeTranscodeLimits(*update.TranscodeLimits)
+			if err != nil {
+				return err
+			}
+			channel.TranscodeLimits = limits
+		}
+		if update.ChatMaxCapsPercent != nil {
+			percent, err := chat.NormalizeMaxCapsPercent(*update.ChatMaxCapsPercent)
+			if err != nil {
+				return err
+			}
+			channel.ChatMaxCapsPercent = percent
+		}
+		if update.ChatRestrictLinks != nil {
+			channel.ChatRestrictLinks = *update.ChatRestrictLinks
+		}
+		if update.ChatSubscribersOnly != nil {
+			channel.ChatSubscribersOnly = *update.ChatSubscribersOnly
+		}
+		if update.ChatWelcomeMessage != nil {
+			welcome, err := chat.NormalizeWelcomeMessage(*update.ChatWelcomeMessage)
+			if err != nil {
+				return err
 			}
+			channel.ChatWelcomeMessage = welcome
+		}
+		limitsPayload, err := encodeTranscodeLimits(channel.TranscodeLimits)
+		if err != nil {
+			return err
 		}
 
 		channel.UpdatedAt = time.Now().UTC()
-		_, err = tx.Exec(ctx, "UPDATE channels SET title = $1, category = $2, tags = $3, live_state = $4, updated_at = $5 WHERE id = $6",
+		_, err = tx.Exec(ctx, "UPDATE channels SET title = $1, category = $2, tags = $3, live_state = $4, playback_restriction = $5, playback_previews = $6, recording_policy = $7, mature_content = $8, transcode_limits = $9, updated_at = $10, starting_since = $11, chat_max_caps_percent = $12, chat_restrict_links = $13, chat_subscribers_only = $14, chat_welcome_message = $15, language = $16, content_warnings = $17 WHERE id = $18",
 			channel.Title,
 			channel.Category,
 			channel.Tags,
 			channel.LiveState,
+			channel.PlaybackRestriction,
+			channel.PlaybackPreviews,
+			channel.RecordingPolicy,
+			channel.MatureContent,
+			limitsPayload,
 			channel.UpdatedAt,
+			channel.StartingSince,
+			channel.ChatMaxCapsPercent,
+			channel.ChatRestrictLinks,
+			channel.ChatSubscribersOnly,
+			channel.ChatWelcomeMessage,
+			channel.Language,
+			nonNilStrings(channel.ContentWarnings),
 			channel.ID,
 		)
 		if err != nil {
 			return fmt.Errorf("update channel %s: %w", id, err)
 		}
-		if err := tx.Commit(ctx); err != nil {
-			return fmt.Errorf("commit update channel: %w", err)
-		}
 		return nil
 	})
 	if err != nil {
@@ -1845,94 +2745,221 @@ func (r *postgresRepository) UpdateChannel(id string, update ChannelUpdate) (mod
 	return channel, nil
 }
 
-func (r *postgresRepository) RotateChannelStreamKey(id string) (models.Channel, error) {
+// BatchUpdateChannels applies update to every listed channel in a single
+// transaction. Unknown channels are reported per channel; any database error
+// rolls the whole batch back.
+func (r *postgresRepository) BatchUpdateChannels(ids []string, update ChannelBatchUpdate) ([]ChannelBatchResult, error) {
 	if r == nil || r.pool == nil {
-		return models.Channel{}, ErrPostgresUnavailable
+		return nil, ErrPostgresUnavailable
 	}
-	var channel models.Channel
-	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
-		if err != nil {
-			return fmt.Errorf("begin rotate stream key tx: %w", err)
-		}
-		defer rollbackTx(ctx, tx)
-
-		var (
-			channelID, ownerID, streamKey, title string
-			category                             pgtype.Text
-			tags                                 []string
-			liveState                            string
-			currentSession                       pgtype.Text
-			createdAt, updatedAt                 time.Time
-		)
-		row := tx.QueryRow(ctx, "SELECT id, owner_id, stream_key, title, category, tags, live_state, current_session_id, created_at, updated_at FROM channels WHERE id = $1 FOR UPDATE", id)
-		if err := row.Scan(&channelID, &ownerID, &streamKey, &title, &category, &tags, &liveState, &currentSession, &createdAt, &updatedAt); err != nil {
+	normalized, err := update.normalized()
+	if err != nil {
+		return nil, err
+	}
+	var results []ChannelBatchResult
+	err = r.withTx(txSpec{Name: "batch update channels", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		now := time.Now().UTC()
+		results = make([]ChannelBatchResult, 0, len(ids))
+		for _, id := range uniqueIDs(ids) {
+			var (
+				category pgtype.Text
+				tags     []string
+			)
+			err := tx.QueryRow(ctx, "SELECT category, tags FROM channels WHERE id = $1 FOR UPDATE", id).Scan(&category, &tags)
 			if errors.Is(err, pgx.ErrNoRows) {
-				return fmt.Errorf("channel %s not found", id)
+				results = append(results, ChannelBatchResult{ChannelID: id, Status: ChannelBatchFailed, Error: fmt.Sprintf("channel %s not found", id)})
+				continue
 			}
-			return fmt.Errorf("load channel %s: %w", id, err)
-		}
-
-		newKey, err := generateStreamKey()
-		if err != nil {
-			return err
-		}
-		now := time.Now().UTC()
-		if _, err := tx.Exec(ctx, "UPDATE channels SET stream_key = $1, updated_at = $2 WHERE id = $3", newKey, now, id); err != nil {
-			return fmt.Errorf("update stream key: %w", err)
-		}
-		if err := tx.Commit(ctx); err != nil {
-			return fmt.Errorf("commit rotate stream key: %w", err)
-		}
-
-		channel = models.Channel{
-			ID:        channelID,
-			OwnerID:   ownerID,
-			StreamKey: newKey,
-			Title:     title,
-			Tags:      append([]string{}, tags...),
-			LiveState: liveState,
-			CreatedAt: createdAt.UTC(),
-			UpdatedAt: now,
-		}
-		if category.Valid {
-			channel.Category = category.String
-		}
-		if currentSession.Valid {
-			current := currentSession.String
-			channel.CurrentSessionID = &current
+			if err != nil {
+				return fmt.Errorf("load channel %s: %w", id, err)
+			}
+			nextCategory, nextTags, changed := normalized.apply(category.String, tags)
+			if !changed {
+				results = append(results, ChannelBatchResult{ChannelID: id, Status: ChannelBatchSkipped})
+				continue
+			}
+			if _, err := tx.Exec(ctx, "UPDATE channels SET category = $1, tags = $2, updated_at = $3 WHERE id = $4", nextCategory, nextTags, now, id); err != nil {
+				return fmt.Errorf("update channel %s: %w", id, err)
+			}
+			results = append(results, ChannelBatchResult{ChannelID: id, Status: ChannelBatchUpdated})
 		}
 		return nil
 	})
 	if err != nil {
-		return models.Channel{}, err
-	}
-	if channel.Tags == nil {
-		channel.Tags = []string{}
+		return nil, err
 	}
-	return channel, nil
+	return results, nil
 }
 
-func (r *postgresRepository) DeleteChannel(id string) error {
+// channelExportPageSize is how many channels ExportChannels loads per query.
+// Rows are handed to the callback between queries so a slow consumer never
+// holds a pooled connection.
+const channelExportPageSize = 500
+
+// ExportChannels pages through channels oldest first and calls fn for each.
+func (r *postgresRepository) ExportChannels(fn func(ChannelExportRow) error) error {
 	if r == nil || r.pool == nil {
 		return ErrPostgresUnavailable
 	}
-	return r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
-		if err != nil {
-			return fmt.Errorf("begin delete channel tx: %w", err)
+	var (
+		afterCreated time.Time
+		afterID      string
+	)
+	for {
+		page := make([]ChannelExportRow, 0, channelExportPageSize)
+		err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+			rows, err := conn.Query(ctx, "SELECT c.id, c.owner_id, u.email, c.title, c.category, c.tags, c.live_state, c.created_at, c.updated_at, (SELECT COUNT(*) FROM follows f WHERE f.channel_id = c.id) FROM channels c JOIN users u ON u.id = c.owner_id WHERE $1 = '' OR (c.created_at, c.id) > ($2, $1) ORDER BY c.created_at ASC, c.id ASC LIMIT $3",
+				afterID,
+				afterCreated,
+				channelExportPageSize,
+			)
+			if err != nil {
+				return fmt.Errorf("list channels for export: %w", err)
+			}
+			defer rows.Close()
+			for rows.Next() {
+				var (
+					row      ChannelExportRow
+					category pgtype.Text
+					tags     []string
+				)
+				if err := rows.Scan(&row.Channel.ID, &row.Channel.OwnerID, &row.OwnerEmail, &row.Channel.Title, &category, &tags, &row.Channel.LiveState, &row.Channel.CreatedAt, &row.Channel.UpdatedAt, &row.Followers); err != nil {
+					return fmt.Errorf("scan channel export row: %w", err)
+				}
+				row.Channel.Category = category.String
+				row.Channel.Tags = append([]string{}, tags...)
+				page = append(page, row)
+			}
+			return rows.Err()
+		})
+		if err != nil {
+			return err
+		}
+		for _, row := range page {
+			row.Channel.CreatedAt = row.Channel.CreatedAt.UTC()
+			row.Channel.UpdatedAt = row.Channel.UpdatedAt.UTC()
+			if err := fn(row); err != nil {
+				return err
+			}
+		}
+		if len(page) < channelExportPageSize {
+			return nil
+		}
+		last := page[len(page)-1].Channel
+		afterCreated, afterID = last.CreatedAt, last.ID
+	}
+}
+
+func (r *postgresRepository) RotateChannelStreamKey(id string) (models.Channel, error) {
+	if r == nil || r.pool == nil {
+		return models.Channel{}, ErrPostgresUnavailable
+	}
+	var channel models.Channel
+	err := r.withTx(txSpec{Name: "rotate stream key", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		var (
+			channelID, ownerID, streamKeyHash, streamKeyHint, title string
+			category                                                pgtype.Text
+			tags                                                    []string
+			liveState                                               string
+			currentSession                                          pgtype.Text
+			createdAt, updatedAt                                    time.Time
+			playbackRestriction                                     string
+			playbackPreviews                                        bool
+			recordingPolicy                                         string
+			matureContent                                           bool
+			transcodeLimits                                         []byte
+			startingSince                                           pgtype.Timestamptz
+			chatMaxCapsPercent                                      int
+			chatRestrictLinks                                       bool
+			chatSubscribersOnly                                     bool
+			chatWelcomeMessage                                      string
+			language                                                string
+			contentWarnings                                         []string
+			slug                                                    string
+			slugChangedAt                                           pgtype.Timestamptz
+		)
+		row := tx.QueryRow(ctx, "SELECT id, owner_id, stream_key_hash, stream_key_hint, title, category, tags, live_state, current_session_id, created_at, updated_at, playback_restriction, playback_previews, recording_policy, mature_content, transcode_limits, starting_since, chat_max_caps_percent, chat_restrict_links, chat_subscribers_only, chat_welcome_message, language, content_warnings, slug, slug_changed_at FROM channels WHERE id = $1 FOR UPDATE", id)
+		if err := row.Scan(&channelID, &ownerID, &streamKeyHash, &streamKeyHint, &title, &category, &tags, &liveState, &currentSession, &createdAt, &updatedAt, &playbackRestriction, &playbackPreviews, &recordingPolicy, &matureContent, &transcodeLimits, &startingSince, &chatMaxCapsPercent, &chatRestrictLinks, &chatSubscribersOnly, &chatWelcomeMessage, &language, &contentWarnings, &slug, &slugChangedAt); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return notFound("channel", id)
+			}
+			return fmt.Errorf("load channel %s: %w", id, err)
+		}
+
+		newKey, newHash, newHint, err := newChannelStreamKey()
+		if err != nil {
+			return err
+		}
+		now := time.Now().UTC()
+		if _, err := tx.Exec(ctx, "UPDATE channels SET stream_key_hash = $1, stream_key_hint = $2, updated_at = $3 WHERE id = $4", newHash, newHint, now, id); err != nil {
+			return fmt.Errorf("update stream key: %w", err)
+		}
+
+		channel = models.Channel{
+			ID:                  channelID,
+			OwnerID:             ownerID,
+			StreamKey:           newKey,
+			StreamKeyHash:       newHash,
+			StreamKeyHint:       newHint,
+			Title:               title,
+			Tags:                append([]string{}, tags...),
+			LiveState:           liveState,
+			CreatedAt:           createdAt.UTC(),
+			UpdatedAt:           now,
+			PlaybackRestriction: playbackRestriction,
+			PlaybackPreviews:    playbackPreviews,
+			RecordingPolicy:     recordingPolicy,
+			MatureContent:       matureContent,
+			ChatMaxCapsPercent:  chatMaxCapsPercent,
+			ChatRestrictLinks:   chatRestrictLinks,
+			ChatSubscribersOnly: chatSubscribersOnly,
+			ChatWelcomeMessage:  chatWelcomeMessage,
+			Language:            language,
+			ContentWarnings:     scannedContentWarnings(contentWarnings),
+			Slug:                slug,
+			SlugChangedAt:       scannedSlugChangedAt(slugChangedAt),
+		}
+		if category.Valid {
+			channel.Category = category.String
+		}
+		if currentSession.Valid {
+			current := currentSession.String
+			channel.CurrentSessionID = &current
 		}
-		defer rollbackTx(ctx, tx)
+		limits, err := decodeTranscodeLimits(transcodeLimits)
+		if err != nil {
+			return err
+		}
+		channel.TranscodeLimits = limits
+		if startingSince.Valid {
+			ts := startingSince.Time.UTC()
+			channel.StartingSince = &ts
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Channel{}, err
+	}
+	if channel.Tags == nil {
+		channel.Tags = []string{}
+	}
+	return channel, nil
+}
 
+func (r *postgresRepository) DeleteChannel(id string) error {
+	if r == nil || r.pool == nil {
+		return ErrPostgresUnavailable
+	}
+	return r.withTx(txSpec{Name: "delete channel", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
 		var currentSession pgtype.Text
 		if err := tx.QueryRow(ctx, "SELECT current_session_id FROM channels WHERE id = $1 FOR UPDATE", id).Scan(&currentSession); err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
-				return fmt.Errorf("channel %s not found", id)
+				return notFound("channel", id)
 			}
 			return fmt.Errorf("load channel %s: %w", id, err)
 		}
 		if currentSession.Valid {
-			return errors.New("cannot delete a channel with an active stream")
+			return precondition("cannot delete a channel with an active stream")
 		}
 
 		if _, err := tx.Exec(ctx, "UPDATE profiles SET featured_channel_id = NULL WHERE featured_channel_id = $1", id); err != nil {
@@ -1941,9 +2968,6 @@ func (r *postgresRepository) DeleteChannel(id string) error {
 		if _, err := tx.Exec(ctx, "DELETE FROM channels WHERE id = $1", id); err != nil {
 			return fmt.Errorf("delete channel %s: %w", id, err)
 		}
-		if err := tx.Commit(ctx); err != nil {
-			return fmt.Errorf("commit delete channel: %w", err)
-		}
 		return nil
 	})
 }
@@ -1955,27 +2979,54 @@ func (r *postgresRepository) GetChannel(id string) (models.Channel, bool) {
 	var channel models.Channel
 	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
 		var (
-			channelID, ownerID, streamKey, title string
-			category                             pgtype.Text
-			tags                                 []string
-			liveState                            string
-			currentSession                       pgtype.Text
-			createdAt, updatedAt                 time.Time
+			channelID, ownerID, streamKeyHash, streamKeyHint, title string
+			category                                                pgtype.Text
+			tags                                                    []string
+			liveState                                               string
+			currentSession                                          pgtype.Text
+			createdAt, updatedAt                                    time.Time
+			playbackRestriction                                     string
+			playbackPreviews                                        bool
+			recordingPolicy                                         string
+			matureContent                                           bool
+			transcodeLimits                                         []byte
+			startingSince                                           pgtype.Timestamptz
+			chatMaxCapsPercent                                      int
+			chatRestrictLinks                                       bool
+			chatSubscribersOnly                                     bool
+			chatWelcomeMessage                                      string
+			language                                                string
+			contentWarnings                                         []string
+			slug                                                    string
+			slugChangedAt                                           pgtype.Timestamptz
 		)
-		err := conn.QueryRow(ctx, "SELECT id, owner_id, stream_key, title, category, tags, live_state, current_session_id, created_at, updated_at FROM channels WHERE id = $1", id).
-			Scan(&channelID, &ownerID, &streamKey, &title, &category, &tags, &liveState, &currentSession, &createdAt, &updatedAt)
+		err := conn.QueryRow(ctx, "SELECT id, owner_id, stream_key_hash, stream_key_hint, title, category, tags, live_state, current_session_id, created_at, updated_at, playback_restriction, playback_previews, recording_policy, mature_content, transcode_limits, starting_since, chat_max_caps_percent, chat_restrict_links, chat_subscribers_only, chat_welcome_message, language, content_warnings, slug, slug_changed_at FROM channels WHERE id = $1", id).
+			Scan(&channelID, &ownerID, &streamKeyHash, &streamKeyHint, &title, &category, &tags, &liveState, &currentSession, &createdAt, &updatedAt, &playbackRestriction, &playbackPreviews, &recordingPolicy, &matureContent, &transcodeLimits, &startingSince, &chatMaxCapsPercent, &chatRestrictLinks, &chatSubscribersOnly, &chatWelcomeMessage, &language, &contentWarnings, &slug, &slugChangedAt)
 		if err != nil {
 			return err
 		}
 		channel = models.Channel{
-			ID:        channelID,
-			OwnerID:   ownerID,
-			StreamKey: streamKey,
-			Title:     title,
-			Tags:      append([]string{}, tags...),
-			LiveState: liveState,
-			CreatedAt: createdAt.UTC(),
-			UpdatedAt: updatedAt.UTC(),
+			ID:                  channelID,
+			OwnerID:             ownerID,
+			StreamKeyHash:       streamKeyHash,
+			StreamKeyHint:       streamKeyHint,
+			Title:               title,
+			Tags:                append([]string{}, tags...),
+			LiveState:           liveState,
+			CreatedAt:           createdAt.UTC(),
+			UpdatedAt:           updatedAt.UTC(),
+			PlaybackRestriction: playbackRestriction,
+			PlaybackPreviews:    playbackPreviews,
+			RecordingPolicy:     recordingPolicy,
+			MatureContent:       matureContent,
+			ChatMaxCapsPercent:  chatMaxCapsPercent,
+			ChatRestrictLinks:   chatRestrictLinks,
+			ChatSubscribersOnly: chatSubscribersOnly,
+			ChatWelcomeMessage:  chatWelcomeMessage,
+			Language:            language,
+			ContentWarnings:     scannedContentWarnings(contentWarnings),
+			Slug:                slug,
+			SlugChangedAt:       scannedSlugChangedAt(slugChangedAt),
 		}
 		if category.Valid {
 			channel.Category = category.String
@@ -1984,6 +3035,15 @@ func (r *postgresRepository) GetChannel(id string) (models.Channel, bool) {
 			current := currentSession.String
 			channel.CurrentSessionID = &current
 		}
+		limits, err := decodeTranscodeLimits(transcodeLimits)
+		if err != nil {
+			return err
+		}
+		channel.TranscodeLimits = limits
+		if startingSince.Valid {
+			ts := startingSince.Time.UTC()
+			channel.StartingSince = &ts
+		}
 		return nil
 	})
 	if errors.Is(err, pgx.ErrNoRows) || err != nil {
@@ -1995,12 +3055,12 @@ func (r *postgresRepository) GetChannel(id string) (models.Channel, bool) {
 	return channel, true
 }
 
-func (r *postgresRepository) GetChannelByStreamKey(streamKey string) (models.Channel, bool) {
+func (r *postgresRepository) FindChannelByStreamKeyHash(hash string) (models.Channel, bool) {
 	if r == nil || r.pool == nil {
 		return models.Channel{}, false
 	}
-	key := strings.TrimSpace(streamKey)
-	if key == "" {
+	hash = strings.TrimSpace(hash)
+	if hash == "" {
 		return models.Channel{}, false
 	}
 
@@ -2008,20 +3068,26 @@ func (r *postgresRepository) GetChannelByStreamKey(streamKey string) (models.Cha
 	found := false
 	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
 		var (
-			category       pgtype.Text
-			tags           []string
-			currentSession pgtype.Text
-			createdAt      time.Time
-			updatedAt      time.Time
+			category        pgtype.Text
+			tags            []string
+			currentSession  pgtype.Text
+			createdAt       time.Time
+			updatedAt       time.Time
+			limits          []byte
+			startingSince   pgtype.Timestamptz
+			contentWarnings []string
+			slugChangedAt   pgtype.Timestamptz
 		)
-		row := conn.QueryRow(ctx, "SELECT id, owner_id, stream_key, title, category, tags, live_state, current_session_id, created_at, updated_at FROM channels WHERE stream_key = $1", key)
-		if err := row.Scan(&channel.ID, &channel.OwnerID, &channel.StreamKey, &channel.Title, &category, &tags, &channel.LiveState, &currentSession, &createdAt, &updatedAt); err != nil {
+		row := conn.QueryRow(ctx, "SELECT id, owner_id, stream_key_hash, stream_key_hint, title, category, tags, live_state, current_session_id, created_at, updated_at, playback_restriction, playback_previews, recording_policy, mature_content, transcode_limits, starting_since, chat_max_caps_percent, chat_restrict_links, chat_subscribers_only, chat_welcome_message, language, content_warnings, slug, slug_changed_at FROM channels WHERE stream_key_hash = $1", hash)
+		if err := row.Scan(&channel.ID, &channel.OwnerID, &channel.StreamKeyHash, &channel.StreamKeyHint, &channel.Title, &category, &tags, &channel.LiveState, &currentSession, &createdAt, &updatedAt, &channel.PlaybackRestriction, &channel.PlaybackPreviews, &channel.RecordingPolicy, &channel.MatureContent, &limits, &startingSince, &channel.ChatMaxCapsPercent, &channel.ChatRestrictLinks, &channel.ChatSubscribersOnly, &channel.ChatWelcomeMessage, &channel.Language, &contentWarnings, &channel.Slug, &slugChangedAt); err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
 				return nil
 			}
-			return fmt.Errorf("load channel by stream key: %w", err)
+			return fmt.Errorf("load channel by stream key hash: %w", err)
 		}
 		channel.Tags = append([]string{}, tags...)
+		channel.ContentWarnings = scannedContentWarnings(contentWarnings)
+		channel.SlugChangedAt = scannedSlugChangedAt(slugChangedAt)
 		if category.Valid {
 			channel.Category = category.String
 		}
@@ -2029,6 +3095,15 @@ func (r *postgresRepository) GetChannelByStreamKey(streamKey string) (models.Cha
 			id := currentSession.String
 			channel.CurrentSessionID = &id
 		}
+		decoded, err := decodeTranscodeLimits(limits)
+		if err != nil {
+			return err
+		}
+		channel.TranscodeLimits = decoded
+		if startingSince.Valid {
+			ts := startingSince.Time.UTC()
+			channel.StartingSince = &ts
+		}
 		channel.CreatedAt = createdAt.UTC()
 		channel.UpdatedAt = updatedAt.UTC()
 		found = true
@@ -2040,60 +3115,108 @@ func (r *postgresRepository) GetChannelByStreamKey(streamKey string) (models.Cha
 	return channel, true
 }
 
-func (r *postgresRepository) ListChannels(ownerID, query string) []models.Channel {
+func (r *postgresRepository) ListChannels(ownerID, query string) ([]models.Channel, error) {
+	return r.ListChannelsMatching(ChannelFilter{OwnerID: ownerID, Query: query})
+}
+
+func (r *postgresRepository) ListChannelsMatching(filter ChannelFilter) ([]models.Channel, error) {
 	if r == nil || r.pool == nil {
-		return nil
+		return nil, ErrPostgresUnavailable
+	}
+	filter, err := filter.normalized()
+	if err != nil {
+		return nil, err
 	}
 	ctx, cancel := r.acquireContext()
 	defer cancel()
-	baseQuery := "SELECT c.id, c.owner_id, c.stream_key, c.title, c.category, c.tags, c.live_state, c.current_session_id, c.created_at, c.updated_at FROM channels c JOIN users u ON u.id = c.owner_id"
-	trimmedOwner := strings.TrimSpace(ownerID)
-	trimmedQuery := strings.TrimSpace(query)
+	baseQuery := "SELECT c.id, c.owner_id, c.stream_key_hash, c.stream_key_hint, c.title, c.category, c.tags, c.live_state, c.current_session_id, c.created_at, c.updated_at, c.playback_restriction, c.playback_previews, c.recording_policy, c.mature_content, c.transcode_limits, c.starting_since, c.chat_max_caps_percent, c.chat_restrict_links, c.chat_subscribers_only, c.chat_welcome_message, c.language, c.content_warnings, c.slug, c.slug_changed_at FROM channels c JOIN users u ON u.id = c.owner_id"
 	var (
 		args    []interface{}
 		clauses []string
 	)
-	if trimmedOwner != "" {
-		args = append(args, trimmedOwner)
+	if filter.OwnerID != "" {
+		args = append(args, filter.OwnerID)
 		clauses = append(clauses, fmt.Sprintf("c.owner_id = $%d", len(args)))
 	}
-	if trimmedQuery != "" {
-		args = append(args, "%"+trimmedQuery+"%")
+	if filter.Query != "" {
+		args = append(args, "%"+filter.Query+"%")
 		argPos := len(args)
 		clauses = append(clauses, fmt.Sprintf("(c.title ILIKE $%[1]d OR u.display_name ILIKE $%[1]d OR EXISTS (SELECT 1 FROM unnest(c.tags) AS tag WHERE tag ILIKE $%[1]d))", argPos))
 	}
+	if filter.Language != "" {
+		args = append(args, filter.Language)
+		clauses = append(clauses, fmt.Sprintf("c.language = $%d", len(args)))
+	}
+	if len(filter.ExcludeWarnings) > 0 {
+		args = append(args, filter.ExcludeWarnings)
+		clauses = append(clauses, fmt.Sprintf("NOT (c.content_warnings && $%d::text[])", len(args)))
+	}
 	if len(clauses) > 0 {
 		baseQuery += " WHERE " + strings.Join(clauses, " AND ")
 	}
-	baseQuery += " ORDER BY CASE WHEN c.live_state = 'live' THEN 0 ELSE 1 END, c.created_at ASC"
-	rows, err := r.pool.Query(ctx, baseQuery, args...)
+	baseQuery += " ORDER BY CASE WHEN c.live_state = 'live' THEN 0 ELSE 1 END, c.created_at ASC, c.id ASC"
+	// The directory tolerates replication lag: a channel created or renamed
+	// a moment ago shows up on a later refresh.
+	conn, err := r.acquireReadConn(ctx)
 	if err != nil {
-		return nil
+		return nil, err
+	}
+	defer conn.Release()
+	rows, err := conn.Query(ctx, baseQuery, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list channels: %w", err)
 	}
 	defer rows.Close()
 
 	channels := make([]models.Channel, 0)
 	for rows.Next() {
 		var (
-			channelID, ownerIDVal, streamKey, title string
-			category                                pgtype.Text
-			tags                                    []string
-			liveState                               string
-			currentSession                          pgtype.Text
-			createdAt, updatedAt                    time.Time
+			channelID, ownerIDVal, streamKeyHash, streamKeyHint, title string
+			category                                                   pgtype.Text
+			tags                                                       []string
+			liveState                                                  string
+			currentSession                                             pgtype.Text
+			createdAt, updatedAt                                       time.Time
+			playbackRestriction                                        string
+			playbackPreviews                                           bool
+			recordingPolicy                                            string
+			matureContent                                              bool
+			transcodeLimits                                            []byte
+			startingSince                                              pgtype.Timestamptz
+			chatMaxCapsPercent                                         int
+			chatRestrictLinks                                          bool
+			chatSubscribersOnly                                        bool
+			chatWelcomeMessage                                         string
+			language                                                   string
+			contentWarnings                                            []string
+			slug                                                       string
+			slugChangedAt                                              pgtype.Timestamptz
 		)
-		if err := rows.Scan(&channelID, &ownerIDVal, &streamKey, &title, &category, &tags, &liveState, &currentSession, &createdAt, &updatedAt); err != nil {
-			return nil
+		if err := rows.Scan(&channelID, &ownerIDVal, &streamKeyHash, &streamKeyHint, &title, &category, &tags, &liveState, &currentSession, &createdAt, &updatedAt, &playbackRestriction, &playbackPreviews, &recordingPolicy, &matureContent, &transcodeLimits, &startingSince, &chatMaxCapsPercent, &chatRestrictLinks, &chatSubscribersOnly, &chatWelcomeMessage, &language, &contentWarnings, &slug, &slugChangedAt); err != nil {
+			return nil, fmt.Errorf("scan channel: %w", err)
 		}
 		channel := models.Channel{
-			ID:        channelID,
-			OwnerID:   ownerIDVal,
-			StreamKey: streamKey,
-			Title:     title,
-			Tags:      append([]string{}, tags...),
-			LiveState: liveState,
-			CreatedAt: createdAt.UTC(),
-			UpdatedAt: updatedAt.UTC(),
+			ID:                  channelID,
+			OwnerID:             ownerIDVal,
+			StreamKeyHash:       streamKeyHash,
+			StreamKeyHint:       streamKeyHint,
+			Title:               title,
+			Tags:                append([]string{}, tags...),
+			LiveState:           liveState,
+			CreatedAt:           createdAt.UTC(),
+			UpdatedAt:           updatedAt.UTC(),
+			PlaybackRestriction: playbackRestriction,
+			PlaybackPreviews:    playbackPreviews,
+			RecordingPolicy:     recordingPolicy,
+			MatureContent:       matureContent,
+			ChatMaxCapsPercent:  chatMaxCapsPercent,
+			ChatRestrictLinks:   chatRestrictLinks,
+			ChatSubscribersOnly: chatSubscribersOnly,
+			ChatWelcomeMessage:  chatWelcomeMessage,
+			Language:            language,
+			ContentWarnings:     scannedContentWarnings(contentWarnings),
+			Slug:                slug,
+			SlugChangedAt:       scannedSlugChangedAt(slugChangedAt),
 		}
 		if category.Valid {
 			channel.Category = category.String
@@ -2102,28 +3225,31 @@ func (r *postgresRepository) ListChannels(ownerID, query string) []models.Channe
 			current := currentSession.String
 			channel.CurrentSessionID = &current
 		}
+		limits, err := decodeTranscodeLimits(transcodeLimits)
+		if err != nil {
+			return nil, fmt.Errorf("decode transcode limits for channel %s: %w", channelID, err)
+		}
+		channel.TranscodeLimits = limits
+		if startingSince.Valid {
+			ts := startingSince.Time.UTC()
+			channel.StartingSince = &ts
+		}
 		if channel.Tags == nil {
 			channel.Tags = []string{}
 		}
 		channels = append(channels, channel)
 	}
 	if err := rows.Err(); err != nil {
-		return nil
+		return nil, err
 	}
-	return channels
+	return channels, nil
 }
 
 func (r *postgresRepository) FollowChannel(userID, channelID string) error {
 	if r == nil || r.pool == nil {
 		return ErrPostgresUnavailable
 	}
-	return r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
-		if err != nil {
-			return fmt.Errorf("begin follow channel tx: %w", err)
-		}
-		defer rollbackTx(ctx, tx)
-
+	return r.withTx(txSpec{Name: "follow channel", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
 		if err := ensureUserExists(ctx, tx, userID); err != nil {
 			return err
 		}
@@ -2134,9 +3260,6 @@ func (r *postgresRepository) FollowChannel(userID, channelID string) error {
 		if _, err := tx.Exec(ctx, "INSERT INTO follows (user_id, channel_id, followed_at) VALUES ($1, $2, NOW()) ON CONFLICT DO NOTHING", userID, channelID); err != nil {
 			return fmt.Errorf("follow channel %s: %w", channelID, err)
 		}
-		if err := tx.Commit(ctx); err != nil {
-			return fmt.Errorf("commit follow channel: %w", err)
-		}
 		return nil
 	})
 }
@@ -2145,13 +3268,7 @@ func (r *postgresRepository) UnfollowChannel(userID, channelID string) error {
 	if r == nil || r.pool == nil {
 		return ErrPostgresUnavailable
 	}
-	return r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
-		if err != nil {
-			return fmt.Errorf("begin unfollow channel tx: %w", err)
-		}
-		defer rollbackTx(ctx, tx)
-
+	return r.withTx(txSpec{Name: "unfollow channel", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
 		if err := ensureUserExists(ctx, tx, userID); err != nil {
 			return err
 		}
@@ -2162,9 +3279,6 @@ func (r *postgresRepository) UnfollowChannel(userID, channelID string) error {
 		if _, err := tx.Exec(ctx, "DELETE FROM follows WHERE user_id = $1 AND channel_id = $2", userID, channelID); err != nil {
 			return fmt.Errorf("unfollow channel %s: %w", channelID, err)
 		}
-		if err := tx.Commit(ctx); err != nil {
-			return fmt.Errorf("commit unfollow channel: %w", err)
-		}
 		return nil
 	})
 }
@@ -2183,12 +3297,15 @@ func (r *postgresRepository) IsFollowingChannel(userID, channelID string) bool {
 	return exists
 }
 
+// CountFollowers reads from a replica when one is configured, so the count can
+// trail a follow by the replication lag. Requests that follow or unfollow
+// read it back through RepositoryForContext.
 func (r *postgresRepository) CountFollowers(channelID string) int {
 	if r == nil || r.pool == nil {
 		return 0
 	}
 	var count int
-	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+	err := r.withReadConn(func(ctx context.Context, conn *pgxpool.Conn) error {
 		return conn.QueryRow(ctx, "SELECT COUNT(*) FROM follows WHERE channel_id = $1", channelID).Scan(&count)
 	})
 	if err != nil {
@@ -2197,15 +3314,56 @@ func (r *postgresRepository) CountFollowers(channelID string) int {
 	return count
 }
 
-func (r *postgresRepository) ListFollowedChannelIDs(userID string) []string {
+// ListChannelFollowers pages the channel's followers newest first. Follows
+// cascade away with their users, and the join keeps any stragglers out. It
+// reads from a replica when one is configured.
+func (r *postgresRepository) ListChannelFollowers(channelID string, opts FollowerListOptions) (FollowerPage, error) {
 	if r == nil || r.pool == nil {
-		return nil
+		return FollowerPage{}, ErrPostgresUnavailable
+	}
+	page := FollowerPage{Followers: make([]ChannelFollower, 0)}
+	err := r.withReadConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		if err := conn.QueryRow(ctx, "SELECT COUNT(*) FROM follows f JOIN users u ON u.id = f.user_id WHERE f.channel_id = $1", channelID).Scan(&page.Total); err != nil {
+			return err
+		}
+		if page.Total == 0 {
+			return nil
+		}
+		query, args := appendLimitOffset(
+			"SELECT u.id, u.display_name, COALESCE(p.avatar_url, ''), f.followed_at FROM follows f JOIN users u ON u.id = f.user_id LEFT JOIN profiles p ON p.user_id = u.id WHERE f.channel_id = $1 ORDER BY f.followed_at DESC, u.id DESC",
+			[]interface{}{channelID},
+			UserListOptions{Limit: opts.Limit, Offset: opts.Offset},
+		)
+		rows, err := conn.Query(ctx, query, args...)
+		if err != nil {
+			return err
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var follower ChannelFollower
+			if err := rows.Scan(&follower.UserID, &follower.DisplayName, &follower.AvatarURL, &follower.FollowedAt); err != nil {
+				return err
+			}
+			follower.FollowedAt = follower.FollowedAt.UTC()
+			page.Followers = append(page.Followers, follower)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return FollowerPage{}, fmt.Errorf("list channel followers: %w", err)
+	}
+	return page, nil
+}
+
+func (r *postgresRepository) ListFollowedChannelIDs(userID string) ([]string, error) {
+	if r == nil || r.pool == nil {
+		return nil, ErrPostgresUnavailable
 	}
 	ids := make([]string, 0)
 	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		rows, err := conn.Query(ctx, "SELECT channel_id FROM follows WHERE user_id = $1 ORDER BY followed_at DESC", userID)
+		rows, err := conn.Query(ctx, "SELECT channel_id FROM follows WHERE user_id = $1 ORDER BY followed_at DESC, channel_id ASC", userID)
 		if err != nil {
-			return err
+			return fmt.Errorf("list followed channels: %w", err)
 		}
 		defer rows.Close()
 
@@ -2219,41 +3377,191 @@ func (r *postgresRepository) ListFollowedChannelIDs(userID string) []string {
 		return rows.Err()
 	})
 	if err != nil {
+		return nil, err
+	}
+	return ids, nil
+}
+
+func (r *postgresRepository) GrantChannelEditor(channelID, userID, grantedBy string, expiresAt *time.Time) (models.ChannelEditor, error) {
+	if r == nil || r.pool == nil {
+		return models.ChannelEditor{}, ErrPostgresUnavailable
+	}
+	channelID = strings.TrimSpace(channelID)
+	userID = strings.TrimSpace(userID)
+	grantedBy = strings.TrimSpace(grantedBy)
+	now := r.grantTime()
+	expires, err := normalizeGrantExpiry(now, expiresAt)
+	if err != nil {
+		return models.ChannelEditor{}, err
+	}
+	var editor models.ChannelEditor
+	err = r.withTx(txSpec{Name: "grant channel editor", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		if err := ensureChannelExists(ctx, tx, channelID); err != nil {
+			return err
+		}
+		var roles []string
+		if err := tx.QueryRow(ctx, "SELECT roles FROM users WHERE id = $1", userID).Scan(&roles); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return notFound("user", userID)
+			}
+			return fmt.Errorf("load user %s: %w", userID, err)
+		}
+		if !(models.User{Roles: roles}).HasRole(authz.RoleEditor) {
+			return fmt.Errorf("user %s does not have the %s role", userID, authz.RoleEditor)
+		}
+
+		var grantor pgtype.Text
+		if grantedBy != "" {
+			grantor = pgtype.Text{String: grantedBy, Valid: true}
+		}
+		var (
+			grantedAt time.Time
+			expiry    pgtype.Timestamptz
+		)
+		if err := tx.QueryRow(ctx, upsertChannelGrantSQL("channel_editors"), channelID, userID, grantor, now, expires).Scan(&grantor, &grantedAt, &expiry); err != nil {
+			return fmt.Errorf("grant channel editor %s: %w", userID, err)
+		}
+		editor = models.ChannelEditor{ChannelID: channelID, UserID: userID, GrantedAt: grantedAt.UTC(), ExpiresAt: grantExpiryFromPG(expiry)}
+		if grantor.Valid {
+			editor.GrantedBy = grantor.String
+		}
 		return nil
+	})
+	if err != nil {
+		return models.ChannelEditor{}, err
 	}
-	return ids
+	return editor, nil
 }
 
-func (r *postgresRepository) StartStream(channelID string, renditions []string) (models.StreamSession, error) {
+func (r *postgresRepository) RevokeChannelEditor(channelID, userID string) error {
 	if r == nil || r.pool == nil {
-		return models.StreamSession{}, ErrPostgresUnavailable
+		return ErrPostgresUnavailable
 	}
-	var (
-		streamKey      string
-		sessionID      string
-		startedAt      time.Time
-		currentSession pgtype.Text
-	)
+	channelID = strings.TrimSpace(channelID)
+	userID = strings.TrimSpace(userID)
+	return r.withTx(txSpec{Name: "revoke channel editor", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		if err := ensureChannelExists(ctx, tx, channelID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, "DELETE FROM channel_editors WHERE channel_id = $1 AND user_id = $2", channelID, userID); err != nil {
+			return fmt.Errorf("revoke channel editor %s: %w", userID, err)
+		}
+		return nil
+	})
+}
+
+func (r *postgresRepository) ListChannelEditors(channelID string) ([]models.ChannelEditor, error) {
+	if r == nil || r.pool == nil {
+		return nil, ErrPostgresUnavailable
+	}
+	editors := make([]models.ChannelEditor, 0)
 	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+		var exists bool
+		if err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM channels WHERE id = $1)", channelID).Scan(&exists); err != nil {
+			return fmt.Errorf("check channel %s: %w", channelID, err)
+		}
+		if !exists {
+			return notFound("channel", channelID)
+		}
+		rows, err := conn.Query(ctx, "SELECT user_id, granted_by, granted_at, expires_at FROM channel_editors WHERE channel_id = $1 AND (expires_at IS NULL OR expires_at > $2) ORDER BY granted_at, user_id", channelID, r.grantTime())
 		if err != nil {
-			return fmt.Errorf("begin start stream tx: %w", err)
+			return fmt.Errorf("list channel editors: %w", err)
+		}
+		defer rows.Close()
+
+		for rows.Next() {
+			var (
+				editor    models.ChannelEditor
+				grantor   pgtype.Text
+				grantedAt time.Time
+				expiry    pgtype.Timestamptz
+			)
+			if err := rows.Scan(&editor.UserID, &grantor, &grantedAt, &expiry); err != nil {
+				return fmt.Errorf("scan channel editor: %w", err)
+			}
+			editor.ChannelID = channelID
+			editor.GrantedAt = grantedAt.UTC()
+			editor.ExpiresAt = grantExpiryFromPG(expiry)
+			if grantor.Valid {
+				editor.GrantedBy = grantor.String
+			}
+			editors = append(editors, editor)
 		}
-		defer rollbackTx(ctx, tx)
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return editors, nil
+}
+
+func (r *postgresRepository) IsChannelEditor(channelID, userID string) bool {
+	if r == nil || r.pool == nil {
+		return false
+	}
+	var exists bool
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		return conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM channel_editors WHERE channel_id = $1 AND user_id = $2 AND (expires_at IS NULL OR expires_at > $3))", channelID, userID, r.grantTime()).Scan(&exists)
+	})
+	if err != nil {
+		return false
+	}
+	return exists
+}
+
+func (r *postgresRepository) StartStream(channelID string, renditions []string) (models.StreamSession, error) {
+	return r.StartStreamContext(context.Background(), channelID, renditions)
+}
 
+// StartStreamContext reserves a session for the channel and boots ingest
+// for it within ctx. When ctx ends first the channel is left starting and
+// ErrStreamStartTimedOut is returned; RecoverStream later tears down
+// whatever the cut-short boot left behind.
+func (r *postgresRepository) StartStreamContext(ctx context.Context, channelID string, renditions []string) (models.StreamSession, error) {
+	if r == nil || r.pool == nil {
+		return models.StreamSession{}, ErrPostgresUnavailable
+	}
+	var (
+		streamKey       string
+		sessionID       string
+		startedAt       time.Time
+		currentSession  pgtype.Text
+		transcodeLimits *models.TranscodeLimits
+		restreams       []ingest.RestreamTarget
+		language        string
+	)
+	err := r.withTx(txSpec{Name: "start stream", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
 		var (
 			ownerID, title, category pgtype.Text
 			tags                     []string
+			limitsPayload            []byte
+			liveState                string
+			startingSince            pgtype.Timestamptz
 		)
-		row := tx.QueryRow(ctx, "SELECT stream_key, current_session_id, owner_id, title, category, tags FROM channels WHERE id = $1 FOR UPDATE", channelID)
-		if err := row.Scan(&streamKey, &currentSession, &ownerID, &title, &category, &tags); err != nil {
+		row := tx.QueryRow(ctx, "SELECT stream_key_hash, current_session_id, owner_id, title, category, tags, transcode_limits, live_state, starting_since, language FROM channels WHERE id = $1 FOR UPDATE", channelID)
+		if err := row.Scan(&streamKey, &currentSession, &ownerID, &title, &category, &tags, &limitsPayload, &liveState, &startingSince, &language); err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
-				return fmt.Errorf("channel %s not found", channelID)
+				return notFound("channel", channelID)
 			}
 			return fmt.Errorf("load channel %s: %w", channelID, err)
 		}
 		if currentSession.Valid {
-			return errors.New("channel already live")
+			current := models.Channel{LiveState: liveState}
+			current.CurrentSessionID = &currentSession.String
+			if startingSince.Valid {
+				current.StartingSince = &startingSince.Time
+			}
+			return checkStartAllowed(current, time.Now().UTC(), r.startingTimeout)
+		}
+		if ownerID.Valid {
+			if err := r.checkStreamLimit(ctx, tx, ownerID.String, channelID); err != nil {
+				return err
+			}
+		}
+		var err error
+		transcodeLimits, err = decodeTranscodeLimits(limitsPayload)
+		if err != nil {
+			return err
 		}
 
 		sessionID, err = generateID()
@@ -2261,55 +3569,47 @@ func (r *postgresRepository) StartStream(channelID string, renditions []string)
 			return err
 		}
 		startedAt = time.Now().UTC()
-		if _, err := tx.Exec(ctx, "UPDATE channels SET current_session_id = $1, live_state = 'starting', updated_at = $2 WHERE id = $3", sessionID, startedAt, channelID); err != nil {
+		if _, err := tx.Exec(ctx, "UPDATE channels SET current_session_id = $1, live_state = 'starting', starting_since = $2, updated_at = $2 WHERE id = $3", sessionID, startedAt, channelID); err != nil {
 			return fmt.Errorf("mark channel starting: %w", err)
 		}
-		if err := tx.Commit(ctx); err != nil {
-			return fmt.Errorf("commit mark channel starting: %w", err)
+		targets, err := queryRestreamTargets(ctx, tx, channelID)
+		if err != nil {
+			return err
 		}
+		restreams = bootRestreams(r.secrets, targets)
 		return nil
 	})
 	if err != nil {
 		return models.StreamSession{}, err
 	}
 
-	attempts := r.ingestMaxAttempts
-	if attempts <= 0 {
-		attempts = 1
-	}
-	controller := r.ingestController
-	if controller == nil {
+	// abandonStart returns the channel to offline unless a recovery has
+	// already released it, possibly to a newer start.
+	abandonStart := func() {
 		_ = r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-			_, err := conn.Exec(ctx, "UPDATE channels SET current_session_id = NULL, live_state = 'offline', updated_at = NOW() WHERE id = $1", channelID)
+			_, err := conn.Exec(ctx, "UPDATE channels SET current_session_id = NULL, live_state = 'offline', starting_since = NULL, updated_at = NOW() WHERE id = $1 AND current_session_id = $2", channelID, sessionID)
 			return err
 		})
+	}
+	controller := r.ingestController
+	if controller == nil {
+		abandonStart()
 		return models.StreamSession{}, ErrIngestControllerUnavailable
 	}
 	deadline := normalizeIngestTimeout(r.ingestTimeout)
-	var boot ingest.BootResult
-	var bootErr error
-	for attempt := 0; attempt < attempts; attempt++ {
-		bootCtx, cancel := context.WithTimeout(context.Background(), deadline)
-		boot, bootErr = controller.BootStream(bootCtx, ingest.BootParams{
-			ChannelID:  channelID,
-			SessionID:  sessionID,
-			StreamKey:  streamKey,
-			Renditions: append([]string{}, renditions...),
-		})
-		cancel()
-		if bootErr == nil {
-			break
-		}
-		if attempt < attempts-1 && r.ingestRetryInterval > 0 {
-			time.Sleep(r.ingestRetryInterval)
+	boot, err := bootIngest(ctx, controller, ingest.BootParams{
+		ChannelID:       channelID,
+		SessionID:       sessionID,
+		StreamKey:       streamKey,
+		Renditions:      append([]string{}, renditions...),
+		TranscodeLimits: transcodeLimits,
+		Restreams:       restreams,
+	}, r.ingestMaxAttempts, deadline, r.ingestRetryInterval)
+	if err != nil {
+		if !errors.Is(err, ErrStreamStartTimedOut) {
+			abandonStart()
 		}
-	}
-	if bootErr != nil {
-		_ = r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-			_, err := conn.Exec(ctx, "UPDATE channels SET current_session_id = NULL, live_state = 'offline', updated_at = NOW() WHERE id = $1", channelID)
-			return err
-		})
-		return models.StreamSession{}, fmt.Errorf("boot ingest: %w", bootErr)
+		return models.StreamSession{}, err
 	}
 
 	session := models.StreamSession{
@@ -2321,7 +3621,10 @@ func (r *postgresRepository) StartStream(channelID string, renditions []string)
 		OriginURL:      boot.OriginURL,
 		PlaybackURL:    boot.PlaybackURL,
 		IngestJobIDs:   append([]string{}, boot.JobIDs...),
+		PreviewURL:     boot.PreviewURL,
+		Settings:       newStreamSettings(renditions, boot, language),
 	}
+	session.PlaybackEndpoints = clonePlaybackEndpoints(boot.PlaybackEndpoints)
 	ingestEndpoints := make([]string, 0, 2)
 	if boot.PrimaryIngest != "" {
 		ingestEndpoints = append(ingestEndpoints, boot.PrimaryIngest)
@@ -2342,35 +3645,36 @@ func (r *postgresRepository) StartStream(channelID string, renditions []string)
 		session.RenditionManifests = manifests
 	}
 
-	revertChannel := func() {
-		_ = r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-			_, err := conn.Exec(ctx, "UPDATE channels SET current_session_id = NULL, live_state = 'offline', updated_at = NOW() WHERE id = $1", channelID)
-			return err
-		})
-	}
 	shutdownIngest := func() {
 		shutdownCtx, cancel := context.WithTimeout(context.Background(), deadline)
 		_ = controller.ShutdownStream(shutdownCtx, channelID, sessionID, append([]string{}, session.IngestJobIDs...))
 		cancel()
-		revertChannel()
+		abandonStart()
 	}
 
-	persistErr := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
-		if err != nil {
-			return fmt.Errorf("begin persist stream session: %w", err)
-		}
-		defer rollbackTx(ctx, tx)
-
-		if _, err := tx.Exec(ctx, "INSERT INTO stream_sessions (id, channel_id, started_at, renditions, peak_concurrent, origin_url, playback_url, ingest_endpoints, ingest_job_ids) VALUES ($1, $2, $3, $4, 0, $5, $6, $7, $8)",
-			session.ID,
-			session.ChannelID,
-			session.StartedAt,
-			session.Renditions,
-			session.OriginURL,
-			session.PlaybackURL,
-			session.IngestEndpoints,
+	settings, err := encodeStreamSettings(session.Settings)
+	if err != nil {
+		shutdownIngest()
+		return models.StreamSession{}, err
+	}
+	playbackEndpoints, err := encodePlaybackEndpoints(session.PlaybackEndpoints)
+	if err != nil {
+		shutdownIngest()
+		return models.StreamSession{}, err
+	}
+	persistErr := r.withTx(txSpec{Name: "persist stream session", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, "INSERT INTO stream_sessions (id, channel_id, started_at, renditions, peak_concurrent, origin_url, playback_url, ingest_endpoints, ingest_job_ids, settings, preview_url, playback_endpoints) VALUES ($1, $2, $3, $4, 0, $5, $6, $7, $8, $9, $10, $11)",
+			session.ID,
+			session.ChannelID,
+			session.StartedAt,
+			session.Renditions,
+			session.OriginURL,
+			session.PlaybackURL,
+			session.IngestEndpoints,
 			session.IngestJobIDs,
+			settings,
+			session.PreviewURL,
+			playbackEndpoints,
 		); err != nil {
 			return fmt.Errorf("insert stream session: %w", err)
 		}
@@ -2379,11 +3683,13 @@ func (r *postgresRepository) StartStream(channelID string, renditions []string)
 				return fmt.Errorf("insert rendition manifest: %w", err)
 			}
 		}
-		if _, err := tx.Exec(ctx, "UPDATE channels SET current_session_id = $1, live_state = 'live', updated_at = $2 WHERE id = $3", session.ID, session.StartedAt, channelID); err != nil {
+		tag, err := tx.Exec(ctx, "UPDATE channels SET live_state = 'live', starting_since = NULL, updated_at = $2 WHERE id = $3 AND current_session_id = $1", session.ID, session.StartedAt, channelID)
+		if err != nil {
 			return fmt.Errorf("mark channel live: %w", err)
 		}
-		if err := tx.Commit(ctx); err != nil {
-			return fmt.Errorf("commit start stream: %w", err)
+		if tag.RowsAffected() == 0 {
+			// RecoverStream reset the channel while ingest was booting.
+			return ErrStreamStartAborted
 		}
 		return nil
 	})
@@ -2395,7 +3701,73 @@ func (r *postgresRepository) StartStream(channelID string, renditions []string)
 	return session, nil
 }
 
-func (r *postgresRepository) StopStream(channelID string, peakConcurrent int) (session models.StreamSession, err error) {
+func (r *postgresRepository) RecoverStream(channelID string) (StreamRecovery, error) {
+	if r == nil || r.pool == nil {
+		return StreamRecovery{}, ErrPostgresUnavailable
+	}
+	controller := r.ingestController
+	if controller == nil {
+		return StreamRecovery{}, ErrIngestControllerUnavailable
+	}
+	var recovery StreamRecovery
+	err := r.withTx(txSpec{Name: "recover stream", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		var (
+			liveState      string
+			currentSession pgtype.Text
+			startingSince  pgtype.Timestamptz
+		)
+		row := tx.QueryRow(ctx, "SELECT live_state, current_session_id, starting_since FROM channels WHERE id = $1 FOR UPDATE", channelID)
+		if err := row.Scan(&liveState, &currentSession, &startingSince); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return notFound("channel", channelID)
+			}
+			return fmt.Errorf("load channel %s: %w", channelID, err)
+		}
+		current := models.Channel{LiveState: liveState}
+		if currentSession.Valid {
+			current.CurrentSessionID = &currentSession.String
+		}
+		if startingSince.Valid {
+			current.StartingSince = &startingSince.Time
+		}
+		now := time.Now().UTC()
+		if !startIsStuck(current, now, r.startingTimeout) {
+			return ErrStreamNotStuck
+		}
+		recovery.SessionID = currentSession.String
+		if err := tx.QueryRow(ctx, "SELECT ingest_job_ids FROM stream_sessions WHERE id = $1", recovery.SessionID).Scan(&recovery.JobIDs); err != nil && !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("load stream session %s: %w", recovery.SessionID, err)
+		}
+		if _, err := tx.Exec(ctx, "UPDATE channels SET current_session_id = NULL, live_state = 'offline', starting_since = NULL, updated_at = $1 WHERE id = $2", now, channelID); err != nil {
+			return fmt.Errorf("reset channel %s: %w", channelID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return StreamRecovery{}, err
+	}
+	channel, ok := r.GetChannel(channelID)
+	if !ok {
+		return StreamRecovery{}, notFound("channel", channelID)
+	}
+	recovery.Channel = channel
+
+	ctx, cancel := context.WithTimeout(context.Background(), normalizeIngestTimeout(r.ingestTimeout))
+	defer cancel()
+	if err := controller.ShutdownStream(ctx, channelID, recovery.SessionID, recovery.JobIDs); err != nil {
+		recovery.ShutdownErr = fmt.Errorf("shutdown ingest: %w", err)
+	}
+	return recovery, nil
+}
+
+func (r *postgresRepository) StopStream(channelID string, peakConcurrent int) (models.StreamSession, error) {
+	return r.StopStreamContext(context.Background(), channelID, peakConcurrent)
+}
+
+// StopStreamContext tears the channel's ingest down within ctx and ends its
+// session. When ctx ends first the channel stays live and
+// ErrStreamStopTimedOut is returned.
+func (r *postgresRepository) StopStreamContext(ctx context.Context, channelID string, peakConcurrent int) (session models.StreamSession, err error) {
 	if r == nil || r.pool == nil {
 		return models.StreamSession{}, ErrPostgresUnavailable
 	}
@@ -2404,6 +3776,7 @@ func (r *postgresRepository) StopStream(channelID string, peakConcurrent int) (s
 		channelTitle         string
 		channelCategory      pgtype.Text
 		channelTags          []string
+		recordingPolicy      string
 		channelWasLive       bool
 		cleanupAfterShutdown bool
 		stopTimestamp        time.Time
@@ -2417,7 +3790,7 @@ func (r *postgresRepository) StopStream(channelID string, peakConcurrent int) (s
 			timestamp = time.Now().UTC()
 		}
 		cleanupErr := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-			if _, execErr := conn.Exec(ctx, "UPDATE channels SET current_session_id = NULL, live_state = 'offline', updated_at = $1 WHERE id = $2", timestamp, channelID); execErr != nil {
+			if _, execErr := conn.Exec(ctx, "UPDATE channels SET current_session_id = NULL, live_state = 'offline', starting_since = NULL, updated_at = $1 WHERE id = $2", timestamp, channelID); execErr != nil {
 				return fmt.Errorf("update channel %s: %w", channelID, execErr)
 			}
 			return nil
@@ -2427,13 +3800,8 @@ func (r *postgresRepository) StopStream(channelID string, peakConcurrent int) (s
 		}
 	}()
 
-	err = r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
-		if err != nil {
-			return fmt.Errorf("begin stop stream tx: %w", err)
-		}
-		defer rollbackTx(ctx, tx)
-
+	err = r.withTx(txSpec{Name: "stop stream", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		channelWasLive = false
 		var (
 			streamKey       string
 			currentSession  pgtype.Text
@@ -2446,26 +3814,38 @@ func (r *postgresRepository) StopStream(channelID string, peakConcurrent int) (s
 			originURL       string
 			playbackURL     string
 		)
-		row := tx.QueryRow(ctx, "SELECT stream_key, current_session_id, title, category, tags FROM channels WHERE id = $1 FOR UPDATE", channelID)
-		if err := row.Scan(&streamKey, &currentSession, &channelTitle, &channelCategory, &channelTags); err != nil {
+		row := tx.QueryRow(ctx, "SELECT stream_key_hash, current_session_id, title, category, tags, recording_policy FROM channels WHERE id = $1 FOR UPDATE", channelID)
+		if err := row.Scan(&streamKey, &currentSession, &channelTitle, &channelCategory, &channelTags, &recordingPolicy); err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
-				return fmt.Errorf("channel %s not found", channelID)
+				return notFound("channel", channelID)
 			}
 			return fmt.Errorf("load channel %s: %w", channelID, err)
 		}
 		if !currentSession.Valid {
-			return errors.New("channel is not live")
+			return ErrChannelNotLive
 		}
 		channelWasLive = true
 		sessionID := currentSession.String
 
-		sessRow := tx.QueryRow(ctx, "SELECT started_at, ended_at, renditions, peak_concurrent, origin_url, playback_url, ingest_endpoints, ingest_job_ids FROM stream_sessions WHERE id = $1 FOR UPDATE", sessionID)
-		if err := sessRow.Scan(&startedAt, &endedAt, &renditions, &peak, &originURL, &playbackURL, &ingestEndpoints, &ingestJobIDs); err != nil {
+		var settingsBytes, endpointsBytes []byte
+		var previewURL, publishSource string
+		var receivingMedia bool
+		var confirmedAt, interruptedAt pgtype.Timestamptz
+		sessRow := tx.QueryRow(ctx, "SELECT started_at, ended_at, renditions, peak_concurrent, origin_url, playback_url, ingest_endpoints, ingest_job_ids, preview_url, settings, playback_endpoints, receiving_media, media_confirmed_at, interrupted_at, publish_source FROM stream_sessions WHERE id = $1 FOR UPDATE", sessionID)
+		if err := sessRow.Scan(&startedAt, &endedAt, &renditions, &peak, &originURL, &playbackURL, &ingestEndpoints, &ingestJobIDs, &previewURL, &settingsBytes, &endpointsBytes, &receivingMedia, &confirmedAt, &interruptedAt, &publishSource); err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
 				return fmt.Errorf("session %s missing", sessionID)
 			}
 			return fmt.Errorf("load session %s: %w", sessionID, err)
 		}
+		settings, err := decodeStreamSettings(settingsBytes)
+		if err != nil {
+			return fmt.Errorf("load session %s: %w", sessionID, err)
+		}
+		playbackEndpoints, err := decodePlaybackEndpoints(endpointsBytes)
+		if err != nil {
+			return fmt.Errorf("load session %s: %w", sessionID, err)
+		}
 		manifestsRows, err := tx.Query(ctx, "SELECT name, manifest_url, bitrate FROM stream_session_manifests WHERE session_id = $1", sessionID)
 		if err != nil {
 			return fmt.Errorf("load session manifests: %w", err)
@@ -2488,9 +3868,6 @@ func (r *postgresRepository) StopStream(channelID string, peakConcurrent int) (s
 		if err := manifestsRows.Err(); err != nil {
 			return fmt.Errorf("read session manifests: %w", err)
 		}
-		if err := tx.Commit(ctx); err != nil {
-			return fmt.Errorf("commit load session: %w", err)
-		}
 
 		session = models.StreamSession{
 			ID:                 sessionID,
@@ -2503,7 +3880,12 @@ func (r *postgresRepository) StopStream(channelID string, peakConcurrent int) (s
 			IngestEndpoints:    append([]string{}, ingestEndpoints...),
 			IngestJobIDs:       append([]string{}, ingestJobIDs...),
 			RenditionManifests: append([]models.RenditionManifest{}, manifests...),
+			PreviewURL:         previewURL,
+			PlaybackEndpoints:  playbackEndpoints,
+			Settings:           settings,
+			PublishSource:      publishSource,
 		}
+		setSessionMedia(&session, receivingMedia, confirmedAt, interruptedAt)
 		if endedAt.Valid {
 			ts := endedAt.Time.UTC()
 			session.EndedAt = &ts
@@ -2520,10 +3902,15 @@ func (r *postgresRepository) StopStream(channelID string, peakConcurrent int) (s
 		return models.StreamSession{}, ErrIngestControllerUnavailable
 	}
 
-	shutdownCtx, cancel := context.WithTimeout(context.Background(), deadline)
-	defer cancel()
-	if err := controller.ShutdownStream(shutdownCtx, channelID, session.ID, append([]string{}, session.IngestJobIDs...)); err != nil {
-		return models.StreamSession{}, fmt.Errorf("shutdown ingest: %w", err)
+	// Discarded streams get no recording, so there is no thumbnail to grab.
+	discard := recordingPolicy == models.RecordingPolicyDiscard
+	var frame ingest.Frame
+	if !discard {
+		frame, _ = captureSessionFrame(ctx, controller, deadline, session.IngestJobIDs)
+	}
+	outputBytes, err := shutdownIngest(ctx, controller, channelID, session.ID, append([]string{}, session.IngestJobIDs...), deadline)
+	if err != nil {
+		return models.StreamSession{}, err
 	}
 	cleanupAfterShutdown = true
 
@@ -2533,7 +3920,7 @@ func (r *postgresRepository) StopStream(channelID string, peakConcurrent int) (s
 		session.PeakConcurrent = peakConcurrent
 	}
 
-	channel := models.Channel{ID: channelID, Title: channelTitle}
+	channel := models.Channel{ID: channelID, Title: channelTitle, RecordingPolicy: recordingPolicy}
 	if channelCategory.Valid {
 		channel.Category = channelCategory.String
 	}
@@ -2541,31 +3928,54 @@ func (r *postgresRepository) StopStream(channelID string, peakConcurrent int) (s
 		channel.Tags = append([]string{}, channelTags...)
 	}
 
-	recording, recErr := r.createRecording(session, channel, stopTimestamp)
-	if recErr != nil {
-		return models.StreamSession{}, recErr
-	}
-
-	err = r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
-		if err != nil {
-			return fmt.Errorf("begin finalize stop stream tx: %w", err)
+	var recording models.Recording
+	if !discard {
+		var recErr error
+		recording, recErr = r.createRecording(session, channel, stopTimestamp, frame, outputBytes)
+		if recErr != nil {
+			return models.StreamSession{}, recErr
 		}
-		defer rollbackTx(ctx, tx)
+	}
 
-		if _, err := tx.Exec(ctx, "UPDATE stream_sessions SET ended_at = $1, peak_concurrent = $2 WHERE id = $3", session.EndedAt, session.PeakConcurrent, session.ID); err != nil {
+	err = r.withTx(txSpec{Name: "finalize stop stream", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		// GREATEST keeps a peak UpdateSessionPeak raised after the session
+		// was read.
+		if err := tx.QueryRow(ctx, "UPDATE stream_sessions SET ended_at = $1, peak_concurrent = GREATEST(peak_concurrent, $2) WHERE id = $3 RETURNING peak_concurrent", session.EndedAt, session.PeakConcurrent, session.ID).Scan(&session.PeakConcurrent); err != nil {
 			return fmt.Errorf("update stream session %s: %w", session.ID, err)
 		}
-		if _, err := tx.Exec(ctx, "UPDATE channels SET current_session_id = NULL, live_state = 'offline', updated_at = $1 WHERE id = $2", stopTimestamp, channelID); err != nil {
+		if _, err := tx.Exec(ctx, "UPDATE channels SET current_session_id = NULL, live_state = 'offline', starting_since = NULL, updated_at = $1 WHERE id = $2", stopTimestamp, channelID); err != nil {
 			return fmt.Errorf("update channel %s: %w", channelID, err)
 		}
+		if _, err := tx.Exec(ctx, "UPDATE costream_invites SET status = $1, ended_at = $2 WHERE session_id = $3 AND status IN ($4, $5)", models.CoStreamInviteEnded, stopTimestamp, session.ID, models.CoStreamInvitePending, models.CoStreamInviteAccepted); err != nil {
+			return fmt.Errorf("end co-stream invites of session %s: %w", session.ID, err)
+		}
 		if recording.ID != "" {
-			if err := r.insertRecording(ctx, tx, recording); err != nil {
+			usage, quota, err := r.lockChannelStorage(ctx, tx, channelID)
+			if err != nil {
 				return err
 			}
-		}
-		if err := tx.Commit(ctx); err != nil {
-			return fmt.Errorf("commit stop stream: %w", err)
+			// A recording that does not fit is still kept, but flagged
+			// and left unpublished until the channel frees space.
+			stored := recording
+			if overStorageQuota(usage.UsedBytes+stored.SizeBytes, quota) {
+				stored.OverQuota = true
+				stored.PublishedAt = nil
+				stored.RetainUntil = r.recordingDeadline(stopTimestamp, false)
+			}
+			chapters, err := sessionChapters(ctx, tx, session.ID, stored.DurationSeconds)
+			if err != nil {
+				return err
+			}
+			stored.Chapters = chapters
+			if err := r.insertRecording(ctx, tx, stored); err != nil {
+				return err
+			}
+			if err := adjustChannelStorage(ctx, tx, channelID, stored.SizeBytes, stopTimestamp); err != nil {
+				return err
+			}
+			if _, err := tx.Exec(ctx, "UPDATE clip_exports SET recording_id = $1 WHERE session_id = $2 AND recording_id IS NULL", recording.ID, session.ID); err != nil {
+				return fmt.Errorf("link live clips to recording %s: %w", recording.ID, err)
+			}
 		}
 		return nil
 	})
@@ -2576,6 +3986,13 @@ func (r *postgresRepository) StopStream(channelID string, peakConcurrent int) (s
 	return session, nil
 }
 
+// ChannelPreview returns a still frame from the channel's live transcoder jobs
+// for directory cards. It returns ingest.ErrFrameUnavailable when the channel
+// is offline or no frame has been captured yet.
+func (r *postgresRepository) ChannelPreview(channelID string) (ingest.Frame, error) {
+	return channelPreview(r.ingestController, r.ingestTimeout, r.CurrentStreamSession, channelID)
+}
+
 func (r *postgresRepository) CurrentStreamSession(channelID string) (models.StreamSession, bool) {
 	if r == nil || r.pool == nil {
 		return models.StreamSession{}, false
@@ -2609,9 +4026,9 @@ func (r *postgresRepository) ListStreamSessions(channelID string) ([]models.Stre
 			return fmt.Errorf("check channel %s: %w", channelID, err)
 		}
 		if !exists {
-			return fmt.Errorf("channel %s not found", channelID)
+			return notFound("channel", channelID)
 		}
-		rows, err := conn.Query(ctx, "SELECT id FROM stream_sessions WHERE channel_id = $1 ORDER BY started_at DESC", channelID)
+		rows, err := conn.Query(ctx, "SELECT id FROM stream_sessions WHERE channel_id = $1 ORDER BY started_at DESC, id ASC", channelID)
 		if err != nil {
 			return fmt.Errorf("list sessions: %w", err)
 		}
@@ -2653,7 +4070,7 @@ func (r *postgresRepository) ListRecordings(channelID string, includeUnpublished
 			return fmt.Errorf("check channel %s: %w", channelID, err)
 		}
 		if !exists {
-			return fmt.Errorf("channel %s not found", channelID)
+			return notFound("channel", channelID)
 		}
 		if err := r.purgeExpiredRecordings(ctx, r.retentionTime()); err != nil {
 			slog.Default().Warn("purge expired recordings failed", "channel_id", channelID, "error", err)
@@ -2662,7 +4079,7 @@ func (r *postgresRepository) ListRecordings(channelID string, includeUnpublished
 		if !includeUnpublished {
 			query += " AND published_at IS NOT NULL"
 		}
-		query += " ORDER BY created_at DESC"
+		query += " ORDER BY created_at DESC, id ASC"
 		rows, err := conn.Query(ctx, query, channelID)
 		if err != nil {
 			return fmt.Errorf("list recordings: %w", err)
@@ -2706,12 +4123,9 @@ func (r *postgresRepository) CreateUpload(params CreateUploadParams) (models.Upl
 		return models.Upload{}, fmt.Errorf("channelId is required")
 	}
 	title := strings.TrimSpace(params.Title)
-	if title == "" {
-		title = "Uploaded video"
-	}
 	filename := strings.TrimSpace(params.Filename)
 	if filename == "" {
-		filename = "upload.mp4"
+		filename = fmt.Sprintf("upload-%s.mp4", time.Now().UTC().Format("20060102-150405"))
 	}
 	metadata := make(map[string]string, len(params.Metadata))
 	for k, v := range params.Metadata {
@@ -2725,15 +4139,34 @@ func (r *postgresRepository) CreateUpload(params CreateUploadParams) (models.Upl
 		return models.Upload{}, fmt.Errorf("encode metadata: %w", err)
 	}
 	playbackURL := strings.TrimSpace(params.PlaybackURL)
+	normalizedHash := strings.ToLower(strings.TrimSpace(params.ContentHash))
+	var contentHash any
+	if normalizedHash != "" {
+		contentHash = normalizedHash
+	}
 
 	upload := models.Upload{}
-	err = r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		var exists bool
-		if err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM channels WHERE id = $1)", channelID).Scan(&exists); err != nil {
+	err = r.withTx(txSpec{Name: "create upload", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		var channelTitle string
+		if err := tx.QueryRow(ctx, "SELECT title FROM channels WHERE id = $1", channelID).Scan(&channelTitle); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return notFound("channel", channelID)
+			}
 			return fmt.Errorf("check channel %s: %w", channelID, err)
 		}
-		if !exists {
-			return fmt.Errorf("channel %s not found", channelID)
+		if title == "" {
+			if channelTitle != "" {
+				title = fmt.Sprintf("%s upload", channelTitle)
+			} else {
+				title = "Uploaded video"
+			}
+		}
+		usage, quota, err := r.lockChannelStorage(ctx, tx, channelID)
+		if err != nil {
+			return err
+		}
+		if exceedsStorageQuota(usage.UsedBytes, params.SizeBytes, quota) {
+			return storageQuotaError(usage.UsedBytes, params.SizeBytes, quota)
 		}
 
 		id, err := generateID()
@@ -2741,12 +4174,13 @@ func (r *postgresRepository) CreateUpload(params CreateUploadParams) (models.Upl
 			return err
 		}
 		now := time.Now().UTC()
-		if _, err := conn.Exec(ctx, "INSERT INTO uploads (id, channel_id, title, filename, size_bytes, status, progress, playback_url, metadata, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, 'pending', 0, $6, $7, $8, $9)",
+		if _, err := tx.Exec(ctx, "INSERT INTO uploads (id, channel_id, title, filename, size_bytes, content_hash, status, progress, playback_url, metadata, created_at, updated_at) VALUES ($1, $2, $3, $4, $5, $6, 'pending', 0, $7, $8, $9, $10)",
 			id,
 			channelID,
 			title,
 			filename,
 			params.SizeBytes,
+			contentHash,
 			playbackURL,
 			metadataJSON,
 			now,
@@ -2754,12 +4188,16 @@ func (r *postgresRepository) CreateUpload(params CreateUploadParams) (models.Upl
 		); err != nil {
 			return fmt.Errorf("insert upload: %w", err)
 		}
+		if err := adjustChannelStorage(ctx, tx, channelID, params.SizeBytes, now); err != nil {
+			return err
+		}
 		upload = models.Upload{
 			ID:          id,
 			ChannelID:   channelID,
 			Title:       title,
 			Filename:    filename,
 			SizeBytes:   params.SizeBytes,
+			ContentHash: normalizedHash,
 			Status:      "pending",
 			Progress:    0,
 			Metadata:    metadata,
@@ -2786,9 +4224,9 @@ func (r *postgresRepository) ListUploads(channelID string) ([]models.Upload, err
 			return fmt.Errorf("check channel %s: %w", channelID, err)
 		}
 		if !exists {
-			return fmt.Errorf("channel %s not found", channelID)
+			return notFound("channel", channelID)
 		}
-		rows, err := conn.Query(ctx, "SELECT id FROM uploads WHERE channel_id = $1 ORDER BY created_at DESC", channelID)
+		rows, err := conn.Query(ctx, "SELECT id FROM uploads WHERE channel_id = $1 ORDER BY created_at DESC, id ASC", channelID)
 		if err != nil {
 			return fmt.Errorf("list uploads: %w", err)
 		}
@@ -2835,26 +4273,49 @@ func (r *postgresRepository) GetUpload(id string) (models.Upload, bool) {
 	return upload, true
 }
 
+func (r *postgresRepository) FindReadyUploadByContentHash(channelID, contentHash string) (models.Upload, bool) {
+	if r == nil || r.pool == nil {
+		return models.Upload{}, false
+	}
+	channelID = strings.TrimSpace(channelID)
+	contentHash = strings.ToLower(strings.TrimSpace(contentHash))
+	if channelID == "" || contentHash == "" {
+		return models.Upload{}, false
+	}
+	ctx, cancel := r.acquireContext()
+	defer cancel()
+	var id string
+	err := r.pool.QueryRow(ctx, "SELECT id FROM uploads WHERE channel_id = $1 AND content_hash = $2 AND status = 'ready' ORDER BY created_at DESC, id ASC LIMIT 1", channelID, contentHash).Scan(&id)
+	if err != nil {
+		return models.Upload{}, false
+	}
+	upload, ok, err := r.loadUpload(ctx, id)
+	if err != nil || !ok {
+		return models.Upload{}, false
+	}
+	return upload, true
+}
+
 func (r *postgresRepository) UpdateUpload(id string, update UploadUpdate) (models.Upload, error) {
 	if r == nil || r.pool == nil {
 		return models.Upload{}, ErrPostgresUnavailable
 	}
 	var result models.Upload
-	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
-		if err != nil {
-			return fmt.Errorf("begin update upload tx: %w", err)
-		}
-		defer rollbackTx(ctx, tx)
-
+	err := r.withTx(txSpec{Name: "update upload", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
 		upload, ok, err := r.loadUpload(ctx, id)
 		if err != nil {
 			return fmt.Errorf("load upload %s: %w", id, err)
 		}
 		if !ok {
-			return fmt.Errorf("upload %s not found", id)
+			return notFound("upload", id)
 		}
+		previousBytes := upload.StorageBytes()
 
+		if update.Steps != nil {
+			if err := applyUploadSteps(&upload, update.Steps); err != nil {
+				return err
+			}
+		}
 		if update.Title != nil {
 			if trimmed := strings.TrimSpace(*update.Title); trimmed != "" {
 				upload.Title = trimmed
@@ -2910,6 +4371,12 @@ func (r *postgresRepository) UpdateUpload(id string, update UploadUpdate) (model
 				upload.CompletedAt = &ts
 			}
 		}
+		if update.OutputBytes != nil {
+			if *update.OutputBytes < 0 {
+				return fmt.Errorf("outputBytes must be zero or greater")
+			}
+			upload.OutputBytes = *update.OutputBytes
+		}
 
 		upload.UpdatedAt = time.Now().UTC()
 
@@ -2925,7 +4392,11 @@ func (r *postgresRepository) UpdateUpload(id string, update UploadUpdate) (model
 		if upload.CompletedAt != nil {
 			completedAt = *upload.CompletedAt
 		}
-		if _, err := tx.Exec(ctx, "UPDATE uploads SET title = $1, status = $2, progress = $3, recording_id = $4, playback_url = $5, metadata = $6, error = $7, completed_at = $8, updated_at = $9 WHERE id = $10",
+		stepsJSON, err := encodeUploadSteps(upload.Steps)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, "UPDATE uploads SET title = $1, status = $2, progress = $3, recording_id = $4, playback_url = $5, metadata = $6, error = $7, completed_at = $8, updated_at = $9, output_bytes = $10, steps = $11 WHERE id = $12",
 			upload.Title,
 			upload.Status,
 			upload.Progress,
@@ -2935,12 +4406,20 @@ func (r *postgresRepository) UpdateUpload(id string, update UploadUpdate) (model
 			upload.Error,
 			completedAt,
 			upload.UpdatedAt,
+			upload.OutputBytes,
+			stepsJSON,
 			id,
 		); err != nil {
 			return fmt.Errorf("update upload %s: %w", id, err)
 		}
-		if err := tx.Commit(ctx); err != nil {
-			return fmt.Errorf("commit update upload: %w", err)
+		if delta := upload.StorageBytes() - previousBytes; delta > 0 {
+			if err := adjustChannelStorage(ctx, tx, upload.ChannelID, delta, upload.UpdatedAt); err != nil {
+				return err
+			}
+		} else if delta < 0 {
+			if err := r.freeChannelStorage(ctx, tx, upload.ChannelID, -delta, upload.UpdatedAt); err != nil {
+				return err
+			}
 		}
 		result = upload
 		return nil
@@ -2951,20 +4430,87 @@ func (r *postgresRepository) UpdateUpload(id string, update UploadUpdate) (model
 	return result, nil
 }
 
-func (r *postgresRepository) DeleteUpload(id string) error {
+// UpdateUploadStep rewrites only the steps, progress, and updated_at columns
+// so the processor can report each phase without a full-row update.
+func (r *postgresRepository) UpdateUploadStep(id string, step models.UploadStep) error {
 	if r == nil || r.pool == nil {
 		return ErrPostgresUnavailable
 	}
-	ctx, cancel := r.acquireContext()
-	command, err := r.pool.Exec(ctx, "DELETE FROM uploads WHERE id = $1", id)
-	cancel()
+	step, err := normalizeUploadStep(step)
+	if err != nil {
+		return err
+	}
+	return r.withTx(txSpec{Name: "update upload step", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		var stepsBytes []byte
+		err := tx.QueryRow(ctx, "SELECT steps FROM uploads WHERE id = $1 FOR UPDATE", id).Scan(&stepsBytes)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return notFound("upload", id)
+		}
+		if err != nil {
+			return fmt.Errorf("load upload %s steps: %w", id, err)
+		}
+		steps, err := decodeUploadSteps(stepsBytes)
+		if err != nil {
+			return err
+		}
+		upload := models.Upload{Steps: steps}
+		upload.ApplyStep(step)
+		stepsJSON, err := encodeUploadSteps(upload.Steps)
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, "UPDATE uploads SET steps = $1, progress = $2, updated_at = $3 WHERE id = $4", stepsJSON, upload.Progress, time.Now().UTC(), id); err != nil {
+			return fmt.Errorf("update upload %s step: %w", id, err)
+		}
+		return nil
+	})
+}
+
+func encodeUploadSteps(steps []models.UploadStep) ([]byte, error) {
+	if steps == nil {
+		steps = []models.UploadStep{}
+	}
+	encoded, err := json.Marshal(steps)
 	if err != nil {
-		return fmt.Errorf("delete upload %s: %w", id, err)
+		return nil, fmt.Errorf("encode upload steps: %w", err)
 	}
-	if command.RowsAffected() == 0 {
-		return fmt.Errorf("upload %s not found", id)
+	return encoded, nil
+}
+
+func decodeUploadSteps(data []byte) ([]models.UploadStep, error) {
+	if len(data) == 0 {
+		return nil, nil
 	}
-	return nil
+	var steps []models.UploadStep
+	if err := json.Unmarshal(data, &steps); err != nil {
+		return nil, fmt.Errorf("decode upload steps: %w", err)
+	}
+	if len(steps) == 0 {
+		return nil, nil
+	}
+	return steps, nil
+}
+
+func (r *postgresRepository) DeleteUpload(id string) error {
+	if r == nil || r.pool == nil {
+		return ErrPostgresUnavailable
+	}
+	return r.withTx(txSpec{Name: "delete upload", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		var (
+			channelID   string
+			sizeBytes   int64
+			outputBytes int64
+		)
+		err := tx.QueryRow(ctx, "DELETE FROM uploads WHERE id = $1 RETURNING channel_id, size_bytes, output_bytes", id).Scan(&channelID, &sizeBytes, &outputBytes)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return notFound("upload", id)
+		}
+		if err != nil {
+			return fmt.Errorf("delete upload %s: %w", id, err)
+		}
+		upload := models.Upload{SizeBytes: sizeBytes, OutputBytes: outputBytes}
+		return r.freeChannelStorage(ctx, tx, channelID, upload.StorageBytes(), time.Now().UTC())
+	})
 }
 
 func (r *postgresRepository) GetRecording(id string) (models.Recording, bool) {
@@ -2990,57 +4536,111 @@ func (r *postgresRepository) PublishRecording(id string) (models.Recording, erro
 
 	var recording models.Recording
 	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+		err := r.runTx(ctx, conn, txSpec{Name: "publish recording", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+			var (
+				channelID       string
+				sessionID       string
+				title           string
+				duration        int
+				playbackBaseURL string
+				metadataBytes   []byte
+				createdAt       time.Time
+				retainUntil     pgtype.Timestamptz
+				publishedAt     pgtype.Timestamptz
+				overQuota       bool
+				premiereAt      pgtype.Timestamptz
+			)
+			err := tx.QueryRow(ctx, "SELECT channel_id, session_id, title, duration_seconds, playback_base_url, metadata, created_at, retain_until, published_at, over_quota, premiere_at FROM recordings WHERE id = $1 FOR UPDATE", id).
+				Scan(&channelID, &sessionID, &title, &duration, &playbackBaseURL, &metadataBytes, &createdAt, &retainUntil, &publishedAt, &overQuota, &premiereAt)
+			if errors.Is(err, pgx.ErrNoRows) {
+				return notFound("recording", id)
+			}
+			if err != nil {
+				return fmt.Errorf("load recording %s: %w", id, err)
+			}
+			if publishedAt.Valid {
+				return nil
+			}
+			now := time.Now().UTC()
+			if premiereAt.Valid && premiereAt.Time.After(now) {
+				return ErrRecordingPremiereScheduled
+			}
+			if overQuota {
+				// The flag is normally cleared as soon as space is freed, but
+				// the platform quota may have been raised since.
+				usage, quota, err := r.lockChannelStorage(ctx, tx, channelID)
+				if err != nil {
+					return err
+				}
+				if overStorageQuota(usage.UsedBytes, quota) {
+					return storageQuotaError(usage.UsedBytes, 0, quota)
+				}
+			}
+			if _, err := tx.Exec(ctx, "UPDATE recordings SET published_at = $1, over_quota = FALSE WHERE id = $2", now, id); err != nil {
+				return fmt.Errorf("publish recording %s: %w", id, err)
+			}
+			if deadline := r.recordingDeadline(now, true); deadline != nil {
+				if _, err := tx.Exec(ctx, "UPDATE recordings SET retain_until = $1 WHERE id = $2", deadline, id); err != nil {
+					return fmt.Errorf("update recording retention: %w", err)
+				}
+			}
+			return nil
+		})
 		if err != nil {
-			return fmt.Errorf("begin publish recording tx: %w", err)
+			return err
 		}
-		defer rollbackTx(ctx, tx)
-
-		var (
-			channelID       string
-			sessionID       string
-			title           string
-			duration        int
-			playbackBaseURL string
-			metadataBytes   []byte
-			createdAt       time.Time
-			retainUntil     pgtype.Timestamptz
-			publishedAt     pgtype.Timestamptz
-		)
-		err = tx.QueryRow(ctx, "SELECT channel_id, session_id, title, duration_seconds, playback_base_url, metadata, created_at, retain_until, published_at FROM recordings WHERE id = $1 FOR UPDATE", id).
-			Scan(&channelID, &sessionID, &title, &duration, &playbackBaseURL, &metadataBytes, &createdAt, &retainUntil, &publishedAt)
-		if errors.Is(err, pgx.ErrNoRows) {
-			return fmt.Errorf("recording %s not found", id)
+		rec, _, loadErr := r.loadRecording(ctx, id)
+		if loadErr != nil {
+			return loadErr
 		}
-		if err != nil {
-			return fmt.Errorf("load recording %s: %w", id, err)
+		if rec.ID == "" {
+			return notFound("recording", id)
 		}
-		if publishedAt.Valid {
-			rec, _, loadErr := r.loadRecording(ctx, id)
-			if loadErr != nil {
-				return loadErr
+		recording = rec
+		return nil
+	})
+	if err != nil {
+		return models.Recording{}, err
+	}
+	return recording, nil
+}
+
+// UnpublishRecording hides a published recording from viewers. Its retention
+// falls back to the unpublished window from now.
+func (r *postgresRepository) UnpublishRecording(id string) (models.Recording, error) {
+	if r == nil || r.pool == nil {
+		return models.Recording{}, ErrPostgresUnavailable
+	}
+
+	var recording models.Recording
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		err := r.runTx(ctx, conn, txSpec{Name: "unpublish recording", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+			deadline := r.recordingDeadline(time.Now().UTC(), false)
+			tag, err := tx.Exec(ctx, "UPDATE recordings SET published_at = NULL, retain_until = $1 WHERE id = $2 AND published_at IS NOT NULL", deadline, id)
+			if err != nil {
+				return fmt.Errorf("unpublish recording %s: %w", id, err)
 			}
-			recording = rec
-			return nil
-		}
-		now := time.Now().UTC()
-		if _, err := tx.Exec(ctx, "UPDATE recordings SET published_at = $1 WHERE id = $2", now, id); err != nil {
-			return fmt.Errorf("publish recording %s: %w", id, err)
-		}
-		if deadline := r.recordingDeadline(now, true); deadline != nil {
-			if _, err := tx.Exec(ctx, "UPDATE recordings SET retain_until = $1 WHERE id = $2", deadline, id); err != nil {
-				return fmt.Errorf("update recording retention: %w", err)
+			if tag.RowsAffected() > 0 {
+				return nil
 			}
-		}
-		if err := tx.Commit(ctx); err != nil {
-			return fmt.Errorf("commit publish recording: %w", err)
+			var exists bool
+			if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM recordings WHERE id = $1)", id).Scan(&exists); err != nil {
+				return fmt.Errorf("check recording %s: %w", id, err)
+			}
+			if !exists {
+				return notFound("recording", id)
+			}
+			return nil
+		})
+		if err != nil {
+			return err
 		}
 		rec, _, loadErr := r.loadRecording(ctx, id)
 		if loadErr != nil {
 			return loadErr
 		}
 		if rec.ID == "" {
-			return fmt.Errorf("recording %s not found", id)
+			return notFound("recording", id)
 		}
 		recording = rec
 		return nil
@@ -3063,7 +4663,7 @@ func (r *postgresRepository) DeleteRecording(id string) error {
 	}
 	if !ok {
 		cancel()
-		return fmt.Errorf("recording %s not found", id)
+		return notFound("recording", id)
 	}
 	if err := r.deleteRecordingArtifacts(recording); err != nil {
 		cancel()
@@ -3094,12 +4694,25 @@ func (r *postgresRepository) DeleteRecording(id string) error {
 			return err
 		}
 	}
-	_, err = r.pool.Exec(ctx, "DELETE FROM recordings WHERE id = $1", id)
+	err = r.deleteRecordingRow(ctx, recording)
 	cancel()
-	if err != nil {
-		return fmt.Errorf("delete recording %s: %w", id, err)
-	}
-	return nil
+	return err
+}
+
+// deleteRecordingRow deletes the recording and returns its size to the
+// channel's storage total.
+func (r *postgresRepository) deleteRecordingRow(ctx context.Context, recording models.Recording) error {
+	return r.runTx(ctx, r.pool, txSpec{Name: "delete recording", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		var sizeBytes int64
+		err := tx.QueryRow(ctx, "DELETE FROM recordings WHERE id = $1 RETURNING size_bytes", recording.ID).Scan(&sizeBytes)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("delete recording %s: %w", recording.ID, err)
+		}
+		return r.freeChannelStorage(ctx, tx, recording.ChannelID, sizeBytes, time.Now().UTC())
+	})
 }
 
 func (r *postgresRepository) CreateClipExport(recordingID string, params ClipExportParams) (models.ClipExport, error) {
@@ -3111,7 +4724,7 @@ func (r *postgresRepository) CreateClipExport(recordingID string, params ClipExp
 	}
 	title := strings.TrimSpace(params.Title)
 	if title == "" {
-		return models.ClipExport{}, fmt.Errorf("title is required")
+		return models.ClipExport{}, invalid("title", "title is required")
 	}
 	clip := models.ClipExport{}
 	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
@@ -3123,7 +4736,7 @@ func (r *postgresRepository) CreateClipExport(recordingID string, params ClipExp
 		if err := conn.QueryRow(ctx, "SELECT channel_id, session_id, duration_seconds FROM recordings WHERE id = $1", recordingID).
 			Scan(&channelID, &sessionID, &duration); err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
-				return fmt.Errorf("recording %s not found", recordingID)
+				return notFound("recording", recordingID)
 			}
 			return fmt.Errorf("load recording %s: %w", recordingID, err)
 		}
@@ -3174,6 +4787,8 @@ func (r *postgresRepository) CreateClipExport(recordingID string, params ClipExp
 	return clip, nil
 }
 
+// ListClipExports reads from a replica when one is configured; a clip
+// requested a moment ago appears once the replica catches up.
 func (r *postgresRepository) ListClipExports(recordingID string) ([]models.ClipExport, error) {
 	if r == nil || r.pool == nil {
 		return nil, ErrPostgresUnavailable
@@ -3182,27 +4797,31 @@ func (r *postgresRepository) ListClipExports(recordingID string) ([]models.ClipE
 		return nil, fmt.Errorf("recording id is required")
 	}
 	clips := make([]models.ClipExport, 0)
-	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+	err := r.withReadConn(func(ctx context.Context, conn *pgxpool.Conn) error {
 		var exists bool
 		if err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM recordings WHERE id = $1)", recordingID).Scan(&exists); err != nil {
 			return fmt.Errorf("check recording %s: %w", recordingID, err)
 		}
 		if !exists {
-			return fmt.Errorf("recording %s not found", recordingID)
+			return notFound("recording", recordingID)
 		}
-		rows, err := conn.Query(ctx, "SELECT id, recording_id, channel_id, session_id, title, start_seconds, end_seconds, status, playback_url, created_at, completed_at, storage_object FROM clip_exports WHERE recording_id = $1 ORDER BY created_at DESC", recordingID)
+		rows, err := conn.Query(ctx, "SELECT id, channel_id, session_id, title, start_seconds, end_seconds, status, playback_url, created_at, completed_at, storage_object, created_by FROM clip_exports WHERE recording_id = $1 ORDER BY created_at DESC, id ASC", recordingID)
 		if err != nil {
 			return fmt.Errorf("list clip exports: %w", err)
 		}
 		defer rows.Close()
 		for rows.Next() {
-			var clip models.ClipExport
+			clip := models.ClipExport{RecordingID: recordingID}
 			var completedAt pgtype.Timestamptz
 			var playbackURL pgtype.Text
 			var storageObject pgtype.Text
-			if err := rows.Scan(&clip.ID, &clip.RecordingID, &clip.ChannelID, &clip.SessionID, &clip.Title, &clip.StartSeconds, &clip.EndSeconds, &clip.Status, &playbackURL, &clip.CreatedAt, &completedAt, &storageObject); err != nil {
+			var createdBy pgtype.Text
+			if err := rows.Scan(&clip.ID, &clip.ChannelID, &clip.SessionID, &clip.Title, &clip.StartSeconds, &clip.EndSeconds, &clip.Status, &playbackURL, &clip.CreatedAt, &completedAt, &storageObject, &createdBy); err != nil {
 				return fmt.Errorf("scan clip export: %w", err)
 			}
+			if createdBy.Valid {
+				clip.CreatedBy = createdBy.String
+			}
 			if completedAt.Valid {
 				ts := completedAt.Time.UTC()
 				clip.CompletedAt = &ts
@@ -3223,39 +4842,419 @@ func (r *postgresRepository) ListClipExports(recordingID string) ([]models.ClipE
 	return clips, nil
 }
 
-func (r *postgresRepository) CreateChatMessage(channelID, userID, content string) (models.ChatMessage, error) {
+// CreateLiveClip cuts the last params.DurationSeconds of the channel's live
+// stream and records the clip against the current session. If the stream
+// stopped while the clip was cut, the insert links the clip to the session's
+// recording straight away.
+func (r *postgresRepository) CreateLiveClip(channelID string, params LiveClipParams) (models.ClipExport, error) {
 	if r == nil || r.pool == nil {
-		return models.ChatMessage{}, ErrPostgresUnavailable
-	}
-
-	trimmed := strings.TrimSpace(content)
-	if trimmed == "" {
-		return models.ChatMessage{}, errors.New("message content cannot be empty")
-	}
-	if len([]rune(trimmed)) > 500 {
-		return models.ChatMessage{}, errors.New("message content exceeds 500 characters")
+		return models.ClipExport{}, ErrPostgresUnavailable
 	}
-
-	id, err := generateID()
+	params, err := normalizeLiveClipParams(params)
 	if err != nil {
-		return models.ChatMessage{}, err
+		return models.ClipExport{}, err
+	}
+	err = r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		var channelExists, userExists bool
+		if err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM channels WHERE id = $1), EXISTS (SELECT 1 FROM users WHERE id = $2)", channelID, params.UserID).Scan(&channelExists, &userExists); err != nil {
+			return fmt.Errorf("check clip owner: %w", err)
+		}
+		if !channelExists {
+			return notFound("channel", channelID)
+		}
+		if !userExists {
+			return notFound("user", params.UserID)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.ClipExport{}, err
+	}
+	session, live := r.CurrentStreamSession(channelID)
+	if !live {
+		return models.ClipExport{}, ErrChannelNotLive
 	}
 
-	createdAt := time.Now().UTC()
-	message := models.ChatMessage{}
-	saveErr := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+	captured, err := captureSessionClip(r.ingestController, r.ingestTimeout, session.IngestJobIDs, params.DurationSeconds)
+	if err != nil {
+		return models.ClipExport{}, err
+	}
+	clip, err := newLiveClip(session, params, captured, time.Now().UTC())
+	if err != nil {
+		return models.ClipExport{}, err
+	}
+	err = r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		var recordingID pgtype.Text
+		row := conn.QueryRow(ctx, "INSERT INTO clip_exports (id, recording_id, channel_id, session_id, title, start_seconds, end_seconds, status, playback_url, created_at, completed_at, created_by) VALUES ($1, (SELECT id FROM recordings WHERE session_id = $3 ORDER BY created_at LIMIT 1), $2, $3, $4, $5, $6, $7, $8, $9, $10, $11) RETURNING recording_id",
+			clip.ID,
+			clip.ChannelID,
+			clip.SessionID,
+			clip.Title,
+			clip.StartSeconds,
+			clip.EndSeconds,
+			clip.Status,
+			clip.PlaybackURL,
+			clip.CreatedAt,
+			clip.CompletedAt,
+			clip.CreatedBy,
+		)
+		if err := row.Scan(&recordingID); err != nil {
+			return fmt.Errorf("insert live clip: %w", err)
+		}
+		if recordingID.Valid {
+			clip.RecordingID = recordingID.String
+		}
+		return nil
+	})
+	if err != nil {
+		return models.ClipExport{}, err
+	}
+	return clip, nil
+}
+
+const streamMarkerColumns = "id, channel_id, session_id, label, offset_seconds, created_by, created_at"
+
+func scanStreamMarker(row pgx.Row) (models.StreamMarker, error) {
+	var (
+		marker    models.StreamMarker
+		createdBy pgtype.Text
+		createdAt time.Time
+	)
+	if err := row.Scan(&marker.ID, &marker.ChannelID, &marker.SessionID, &marker.Label, &marker.OffsetSeconds, &createdBy, &createdAt); err != nil {
+		return models.StreamMarker{}, err
+	}
+	if createdBy.Valid {
+		marker.CreatedBy = createdBy.String
+	}
+	marker.CreatedAt = createdAt.UTC()
+	return marker, nil
+}
+
+// CreateStreamMarker marks params.At in the channel's live session. The
+// session row is locked while the markers are counted so concurrent requests
+// cannot overshoot MaxStreamMarkersPerSession.
+func (r *postgresRepository) CreateStreamMarker(channelID string, params StreamMarkerParams) (models.StreamMarker, error) {
+	if r == nil || r.pool == nil {
+		return models.StreamMarker{}, ErrPostgresUnavailable
+	}
+	params, err := normalizeStreamMarkerParams(params)
+	if err != nil {
+		return models.StreamMarker{}, err
+	}
+	var marker models.StreamMarker
+	err = r.withTx(txSpec{Name: "stream marker", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		var current pgtype.Text
+		if err := tx.QueryRow(ctx, "SELECT current_session_id FROM channels WHERE id = $1", channelID).Scan(&current); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return notFound("channel", channelID)
+			}
+			return fmt.Errorf("load channel %s: %w", channelID, err)
+		}
+		var userExists bool
+		if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)", params.UserID).Scan(&userExists); err != nil {
+			return fmt.Errorf("check user %s: %w", params.UserID, err)
+		}
+		if !userExists {
+			return notFound("user", params.UserID)
+		}
+		if !current.Valid {
+			return ErrChannelNotLive
+		}
+		session := models.StreamSession{ID: current.String, ChannelID: channelID}
+		if err := tx.QueryRow(ctx, "SELECT started_at FROM stream_sessions WHERE id = $1 FOR UPDATE", session.ID).Scan(&session.StartedAt); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Errorf("session %s missing", session.ID)
+			}
+			return fmt.Errorf("lock session %s: %w", session.ID, err)
+		}
+		var count int
+		if err := tx.QueryRow(ctx, "SELECT COUNT(*) FROM stream_markers WHERE session_id = $1", session.ID).Scan(&count); err != nil {
+			return fmt.Errorf("count stream markers: %w", err)
+		}
+		if count >= MaxStreamMarkersPerSession {
+			return ErrStreamMarkerLimit
+		}
+		created, err := newStreamMarker(session, params)
 		if err != nil {
-			return fmt.Errorf("begin create chat message tx: %w", err)
+			return err
 		}
-		defer rollbackTx(ctx, tx)
+		if _, err := tx.Exec(ctx, "INSERT INTO stream_markers ("+streamMarkerColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			created.ID,
+			created.ChannelID,
+			created.SessionID,
+			created.Label,
+			created.OffsetSeconds,
+			created.CreatedBy,
+			created.CreatedAt,
+		); err != nil {
+			return fmt.Errorf("insert stream marker: %w", err)
+		}
+		marker = created
+		return nil
+	})
+	if err != nil {
+		return models.StreamMarker{}, err
+	}
+	return marker, nil
+}
 
-		if err := ensureChannelExists(ctx, tx, channelID); err != nil {
+// ListStreamMarkers returns the markers of a session ordered by offset.
+func (r *postgresRepository) ListStreamMarkers(sessionID string) ([]models.StreamMarker, error) {
+	if r == nil || r.pool == nil {
+		return nil, ErrPostgresUnavailable
+	}
+	markers := make([]models.StreamMarker, 0)
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		rows, err := conn.Query(ctx, "SELECT "+streamMarkerColumns+" FROM stream_markers WHERE session_id = $1 ORDER BY offset_seconds, created_at, id", sessionID)
+		if err != nil {
+			return fmt.Errorf("list stream markers: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			marker, err := scanStreamMarker(rows)
+			if err != nil {
+				return fmt.Errorf("scan stream marker: %w", err)
+			}
+			markers = append(markers, marker)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return markers, nil
+}
+
+const restreamTargetColumns = "id, channel_id, label, url, stream_key_ciphertext, enabled, created_at, updated_at"
+
+func scanRestreamTarget(row pgx.Row) (models.RestreamTarget, error) {
+	var target models.RestreamTarget
+	if err := row.Scan(&target.ID, &target.ChannelID, &target.Label, &target.URL, &target.StreamKeyCiphertext, &target.Enabled, &target.CreatedAt, &target.UpdatedAt); err != nil {
+		return models.RestreamTarget{}, err
+	}
+	target.CreatedAt = target.CreatedAt.UTC()
+	target.UpdatedAt = target.UpdatedAt.UTC()
+	return target, nil
+}
+
+// rowsQuerier is satisfied by both pooled connections and transactions.
+type rowsQuerier interface {
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+}
+
+// queryRestreamTargets returns the channel's restream targets in creation
+// order.
+func queryRestreamTargets(ctx context.Context, q rowsQuerier, channelID string) ([]models.RestreamTarget, error) {
+	rows, err := q.Query(ctx, "SELECT "+restreamTargetColumns+" FROM restream_targets WHERE channel_id = $1 ORDER BY created_at, id", channelID)
+	if err != nil {
+		return nil, fmt.Errorf("list restream targets: %w", err)
+	}
+	defer rows.Close()
+	targets := make([]models.RestreamTarget, 0)
+	for rows.Next() {
+		target, err := scanRestreamTarget(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan restream target: %w", err)
+		}
+		targets = append(targets, target)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate restream targets: %w", err)
+	}
+	return targets, nil
+}
+
+// CreateRestreamTarget adds a restream target to the channel. The channel
+// row is locked while its targets are counted so concurrent requests cannot
+// overshoot MaxRestreamTargetsPerChannel.
+func (r *postgresRepository) CreateRestreamTarget(channelID string, params RestreamTargetParams) (models.RestreamTarget, error) {
+	if r == nil || r.pool == nil {
+		return models.RestreamTarget{}, ErrPostgresUnavailable
+	}
+	var target models.RestreamTarget
+	err := r.withTx(txSpec{Name: "restream target", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		var id string
+		if err := tx.QueryRow(ctx, "SELECT id FROM channels WHERE id = $1 FOR UPDATE", channelID).Scan(&id); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return notFound("channel", channelID)
+			}
+			return fmt.Errorf("lock channel %s: %w", channelID, err)
+		}
+		var count int
+		if err := tx.QueryRow(ctx, "SELECT COUNT(*) FROM restream_targets WHERE channel_id = $1", channelID).Scan(&count); err != nil {
+			return fmt.Errorf("count restream targets: %w", err)
+		}
+		if count >= MaxRestreamTargetsPerChannel {
+			return ErrRestreamTargetLimit
+		}
+		created, err := newRestreamTarget(r.secrets, channelID, params, time.Now().UTC())
+		if err != nil {
 			return err
 		}
-		if err := ensureUserExists(ctx, tx, userID); err != nil {
+		if _, err := tx.Exec(ctx, "INSERT INTO restream_targets ("+restreamTargetColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+			created.ID,
+			created.ChannelID,
+			created.Label,
+			created.URL,
+			created.StreamKeyCiphertext,
+			created.Enabled,
+			created.CreatedAt,
+			created.UpdatedAt,
+		); err != nil {
+			return fmt.Errorf("insert restream target: %w", err)
+		}
+		target = created
+		return nil
+	})
+	if err != nil {
+		return models.RestreamTarget{}, err
+	}
+	return target, nil
+}
+
+// ListRestreamTargets returns the channel's restream targets in creation
+// order.
+func (r *postgresRepository) ListRestreamTargets(channelID string) ([]models.RestreamTarget, error) {
+	if r == nil || r.pool == nil {
+		return nil, ErrPostgresUnavailable
+	}
+	var targets []models.RestreamTarget
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		var err error
+		targets, err = queryRestreamTargets(ctx, conn, channelID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return targets, nil
+}
+
+// UpdateRestreamTarget changes one of the channel's restream targets.
+func (r *postgresRepository) UpdateRestreamTarget(channelID, targetID string, update RestreamTargetUpdate) (models.RestreamTarget, error) {
+	if r == nil || r.pool == nil {
+		return models.RestreamTarget{}, ErrPostgresUnavailable
+	}
+	var target models.RestreamTarget
+	err := r.withTx(txSpec{Name: "restream target", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		current, err := scanRestreamTarget(tx.QueryRow(ctx, "SELECT "+restreamTargetColumns+" FROM restream_targets WHERE id = $1 AND channel_id = $2 FOR UPDATE", targetID, channelID))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrRestreamTargetNotFound
+			}
+			return fmt.Errorf("load restream target %s: %w", targetID, err)
+		}
+		updated, err := applyRestreamTargetUpdate(r.secrets, current, update, time.Now().UTC())
+		if err != nil {
 			return err
 		}
+		if _, err := tx.Exec(ctx, "UPDATE restream_targets SET label = $1, url = $2, stream_key_ciphertext = $3, enabled = $4, updated_at = $5 WHERE id = $6",
+			updated.Label,
+			updated.URL,
+			updated.StreamKeyCiphertext,
+			updated.Enabled,
+			updated.UpdatedAt,
+			updated.ID,
+		); err != nil {
+			return fmt.Errorf("update restream target %s: %w", targetID, err)
+		}
+		target = updated
+		return nil
+	})
+	if err != nil {
+		return models.RestreamTarget{}, err
+	}
+	return target, nil
+}
+
+// DeleteRestreamTarget removes one of the channel's restream targets.
+func (r *postgresRepository) DeleteRestreamTarget(channelID, targetID string) error {
+	if r == nil || r.pool == nil {
+		return ErrPostgresUnavailable
+	}
+	return r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		tag, err := conn.Exec(ctx, "DELETE FROM restream_targets WHERE id = $1 AND channel_id = $2", targetID, channelID)
+		if err != nil {
+			return fmt.Errorf("delete restream target %s: %w", targetID, err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrRestreamTargetNotFound
+		}
+		return nil
+	})
+}
+
+// RestreamStatus reports the state of the channel's restreams. It returns
+// ErrChannelNotLive when the channel is offline.
+func (r *postgresRepository) RestreamStatus(channelID string) ([]RestreamTargetStatus, error) {
+	if r == nil || r.pool == nil {
+		return nil, ErrPostgresUnavailable
+	}
+	var targets []models.RestreamTarget
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		var current pgtype.Text
+		if err := conn.QueryRow(ctx, "SELECT current_session_id FROM channels WHERE id = $1", channelID).Scan(&current); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return notFound("channel", channelID)
+			}
+			return fmt.Errorf("load channel %s: %w", channelID, err)
+		}
+		if !current.Valid {
+			return ErrChannelNotLive
+		}
+		var err error
+		targets, err = queryRestreamTargets(ctx, conn, channelID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return fetchRestreamStatus(r.ingestController, r.ingestTimeout, channelID, targets)
+}
+
+func (r *postgresRepository) CreateChatMessage(channelID, userID, content, clientMessageID string) (models.ChatMessage, error) {
+	if r == nil || r.pool == nil {
+		return models.ChatMessage{}, ErrPostgresUnavailable
+	}
+
+	if strings.TrimSpace(content) == "" {
+		return models.ChatMessage{}, errors.New("message content cannot be empty")
+	}
+	clientMessageID, err := chat.NormalizeClientMessageID(clientMessageID)
+	if err != nil {
+		return models.ChatMessage{}, err
+	}
+
+	id, err := generateID()
+	if err != nil {
+		return models.ChatMessage{}, err
+	}
+
+	createdAt := time.Now().UTC()
+	message := models.ChatMessage{}
+	saveErr := r.withTx(txSpec{Name: "create chat message", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		channel := models.Channel{ID: channelID}
+		if err := tx.QueryRow(ctx, "SELECT owner_id, chat_max_caps_percent, chat_restrict_links, chat_subscribers_only FROM channels WHERE id = $1", channelID).Scan(&channel.OwnerID, &channel.ChatMaxCapsPercent, &channel.ChatRestrictLinks, &channel.ChatSubscribersOnly, &channel.ChatWelcomeMessage); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return notFound("channel", channelID)
+			}
+			return fmt.Errorf("load channel %s: %w", channelID, err)
+		}
+		user := models.User{ID: userID}
+		if err := tx.QueryRow(ctx, "SELECT roles FROM users WHERE id = $1", userID).Scan(&user.Roles); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return notFound("user", userID)
+			}
+			return fmt.Errorf("load user %s: %w", userID, err)
+		}
+
+		processed := chat.ProcessContent(content, channel.ChatMaxCapsPercent)
+		if processed.Content == "" {
+			return errors.New("message content cannot be empty")
+		}
+		if len([]rune(processed.Content)) > MaxChatMessageLength {
+			return fmt.Errorf("message content exceeds %d characters", MaxChatMessageLength)
+		}
 
 		var banned bool
 		if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM chat_bans WHERE channel_id = $1 AND user_id = $2)", channelID, userID).Scan(&banned); err != nil {
@@ -3280,20 +5279,46 @@ func (r *postgresRepository) CreateChatMessage(channelID, userID, content string
 			}
 		}
 
-		if _, err := tx.Exec(ctx, "INSERT INTO chat_messages (id, channel_id, user_id, content, created_at) VALUES ($1, $2, $3, $4, $5)", id, channelID, userID, trimmed, createdAt); err != nil {
-			return fmt.Errorf("insert chat message: %w", err)
+		if (len(processed.URLs) > 0 && channel.ChatRestrictLinks) || channel.ChatSubscribersOnly {
+			var following, subscribed, moderator bool
+			if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM follows WHERE user_id = $1 AND channel_id = $2), EXISTS (SELECT 1 FROM subscriptions WHERE channel_id = $2 AND user_id = $1 AND status IN ('active', 'cancelled') AND expires_at > $3), EXISTS (SELECT 1 FROM channel_moderators WHERE channel_id = $2 AND user_id = $1 AND (expires_at IS NULL OR expires_at > $4))", userID, channelID, createdAt, r.grantTime()).Scan(&following, &subscribed, &moderator); err != nil {
+				return fmt.Errorf("check chat access: %w", err)
+			}
+			if !chat.ChatAllowed(channel, user, subscribed, moderator) {
+				return chat.ErrSubscribersOnly
+			}
+			if len(processed.URLs) > 0 && !chat.LinksAllowed(channel, user, following, subscribed, moderator) {
+				return chat.ErrLinksRestricted
+			}
 		}
 
-		if err := tx.Commit(ctx); err != nil {
-			return fmt.Errorf("commit chat message: %w", err)
+		var clientParam any
+		if clientMessageID != "" {
+			if err := releaseExpiredChatClientMessageID(ctx, tx, channelID, userID, clientMessageID, createdAt); err != nil {
+				return err
+			}
+			clientParam = clientMessageID
+		}
+		tag, err := tx.Exec(ctx, "INSERT INTO chat_messages (id, channel_id, user_id, content, client_message_id, created_at) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (channel_id, user_id, client_message_id) WHERE client_message_id IS NOT NULL DO NOTHING", id, channelID, userID, processed.Content, clientParam, createdAt)
+		if err != nil {
+			return fmt.Errorf("insert chat message: %w", err)
+		}
+		if tag.RowsAffected() == 0 {
+			original, err := chatMessageByClientID(ctx, tx, channelID, userID, clientMessageID)
+			if err != nil {
+				return err
+			}
+			message = original
+			return nil
 		}
 
 		message = models.ChatMessage{
-			ID:        id,
-			ChannelID: channelID,
-			UserID:    userID,
-			Content:   trimmed,
-			CreatedAt: createdAt,
+			ID:              id,
+			ChannelID:       channelID,
+			UserID:          userID,
+			Content:         processed.Content,
+			ClientMessageID: clientMessageID,
+			CreatedAt:       createdAt,
 		}
 
 		return nil
@@ -3305,71 +5330,143 @@ func (r *postgresRepository) CreateChatMessage(channelID, userID, content string
 	return message, nil
 }
 
-func (r *postgresRepository) DeleteChatMessage(channelID, messageID string) error {
+// releaseExpiredChatClientMessageID clears clientMessageID from an older
+// message of the user in the channel once chat.ClientMessageIDWindow has
+// passed, so the unique index lets the ID be used again.
+func releaseExpiredChatClientMessageID(ctx context.Context, tx pgx.Tx, channelID, userID, clientMessageID string, now time.Time) error {
+	if _, err := tx.Exec(ctx, "UPDATE chat_messages SET client_message_id = NULL WHERE channel_id = $1 AND user_id = $2 AND client_message_id = $3 AND created_at <= $4", channelID, userID, clientMessageID, now.Add(-chat.ClientMessageIDWindow)); err != nil {
+		return fmt.Errorf("release client message id: %w", err)
+	}
+	return nil
+}
+
+func chatMessageByClientID(ctx context.Context, tx pgx.Tx, channelID, userID, clientMessageID string) (models.ChatMessage, error) {
+	var msg models.ChatMessage
+	var createdAt time.Time
+	err := tx.QueryRow(ctx, "SELECT id, channel_id, user_id, content, client_message_id, created_at FROM chat_messages WHERE channel_id = $1 AND user_id = $2 AND client_message_id = $3", channelID, userID, clientMessageID).Scan(&msg.ID, &msg.ChannelID, &msg.UserID, &msg.Content, &msg.ClientMessageID, &createdAt)
+	if err != nil {
+		return models.ChatMessage{}, fmt.Errorf("load chat message by client id: %w", err)
+	}
+	msg.CreatedAt = createdAt.UTC()
+	return msg, nil
+}
+
+func (r *postgresRepository) DeleteChatMessage(channelID, messageID, actorID string) error {
 	if r == nil || r.pool == nil {
 		return ErrPostgresUnavailable
 	}
 
-	deleteErr := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
-		if err != nil {
-			return fmt.Errorf("begin delete chat message tx: %w", err)
-		}
-		defer rollbackTx(ctx, tx)
-
+	deleteErr := r.withTx(txSpec{Name: "delete chat message", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
 		if err := ensureChannelExists(ctx, tx, channelID); err != nil {
 			return err
 		}
 
-		var existingChannel string
-		if err := tx.QueryRow(ctx, "SELECT channel_id FROM chat_messages WHERE id = $1", messageID).Scan(&existingChannel); err != nil {
+		message := models.ChatMessage{ID: messageID, ChannelID: channelID}
+		row := tx.QueryRow(ctx, "DELETE FROM chat_messages WHERE id = $1 AND channel_id = $2 RETURNING user_id, content", messageID, channelID)
+		if err := row.Scan(&message.UserID, &message.Content); err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
-				return fmt.Errorf("message %s not found for channel %s", messageID, channelID)
+				return nil
 			}
-			return fmt.Errorf("lookup chat message %s: %w", messageID, err)
-		}
-		if existingChannel != channelID {
-			return fmt.Errorf("message %s not found for channel %s", messageID, channelID)
-		}
-
-		if _, err := tx.Exec(ctx, "DELETE FROM chat_messages WHERE id = $1", messageID); err != nil {
 			return fmt.Errorf("delete chat message %s: %w", messageID, err)
 		}
 
-		if err := tx.Commit(ctx); err != nil {
-			return fmt.Errorf("commit delete chat message: %w", err)
-		}
-		return nil
+		return insertModerationAction(ctx, tx, deletedMessageAction(message, actorID, time.Now().UTC()))
 	})
 
 	return deleteErr
 }
 
+// ListChatMessages reads from a replica when one is configured. Clients get
+// new messages over the chat gateway, so history trailing by the replication
+// lag only matters to a client that joins in that window.
 func (r *postgresRepository) ListChatMessages(channelID string, limit int) ([]models.ChatMessage, error) {
 	if r == nil || r.pool == nil {
 		return nil, ErrPostgresUnavailable
 	}
 	ctx, cancel := r.acquireContext()
 	defer cancel()
+	conn, err := r.acquireReadConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
 
 	var exists bool
-	if err := r.pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM channels WHERE id = $1)", channelID).Scan(&exists); err != nil {
+	if err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM channels WHERE id = $1)", channelID).Scan(&exists); err != nil {
 		return nil, fmt.Errorf("check channel %s: %w", channelID, err)
 	}
 	if !exists {
-		return nil, fmt.Errorf("channel %s not found", channelID)
+		return nil, notFound("channel", channelID)
+	}
+
+	query := "SELECT id, channel_id, user_id, content, COALESCE(client_message_id, ''), created_at FROM chat_messages WHERE channel_id = $1 ORDER BY created_at DESC, id ASC"
+	args := []any{channelID}
+	if limit > 0 {
+		query += " LIMIT $2"
+		args = append(args, limit)
+	}
+
+	rows, err := conn.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list chat messages: %w", err)
+	}
+	defer rows.Close()
+
+	messages := make([]models.ChatMessage, 0)
+	for rows.Next() {
+		var msg models.ChatMessage
+		var createdAt time.Time
+		if err := rows.Scan(&msg.ID, &msg.ChannelID, &msg.UserID, &msg.Content, &msg.ClientMessageID, &createdAt); err != nil {
+			return nil, fmt.Errorf("scan chat message: %w", err)
+		}
+		msg.CreatedAt = createdAt.UTC()
+		messages = append(messages, msg)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate chat messages: %w", err)
+	}
+
+	return messages, nil
+}
+
+// ListChatMessagesInRange reads from a replica when one is configured. It
+// serves chat replay for past streams, which is well behind any lag.
+func (r *postgresRepository) ListChatMessagesInRange(params ChatMessageRangeParams) ([]models.ChatMessage, error) {
+	if r == nil || r.pool == nil {
+		return nil, ErrPostgresUnavailable
+	}
+	params, err := normalizeChatMessageRangeParams(params)
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := r.acquireContext()
+	defer cancel()
+	conn, err := r.acquireReadConn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	var exists bool
+	if err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM channels WHERE id = $1)", params.ChannelID).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("check channel %s: %w", params.ChannelID, err)
+	}
+	if !exists {
+		return nil, notFound("channel", params.ChannelID)
 	}
 
-	query := "SELECT id, channel_id, user_id, content, created_at FROM chat_messages WHERE channel_id = $1 ORDER BY created_at DESC, id ASC"
-	args := []any{channelID}
-	if limit > 0 {
-		query += " LIMIT $2"
-		args = append(args, limit)
+	query := "SELECT id, channel_id, user_id, content, COALESCE(client_message_id, ''), created_at FROM chat_messages WHERE channel_id = $1 AND created_at >= $2 AND created_at < $3"
+	args := []any{params.ChannelID, params.Start, params.End}
+	if !params.AfterCreatedAt.IsZero() {
+		query += " AND (created_at, id) > ($4, $5)"
+		args = append(args, params.AfterCreatedAt, params.AfterID)
 	}
+	query += fmt.Sprintf(" ORDER BY created_at ASC, id ASC LIMIT $%d", len(args)+1)
+	args = append(args, params.Limit)
 
-	rows, err := r.pool.Query(ctx, query, args...)
+	rows, err := conn.Query(ctx, query, args...)
 	if err != nil {
-		return nil, fmt.Errorf("list chat messages: %w", err)
+		return nil, fmt.Errorf("list chat messages in range: %w", err)
 	}
 	defer rows.Close()
 
@@ -3377,7 +5474,7 @@ func (r *postgresRepository) ListChatMessages(channelID string, limit int) ([]mo
 	for rows.Next() {
 		var msg models.ChatMessage
 		var createdAt time.Time
-		if err := rows.Scan(&msg.ID, &msg.ChannelID, &msg.UserID, &msg.Content, &createdAt); err != nil {
+		if err := rows.Scan(&msg.ID, &msg.ChannelID, &msg.UserID, &msg.Content, &msg.ClientMessageID, &createdAt); err != nil {
 			return nil, fmt.Errorf("scan chat message: %w", err)
 		}
 		msg.CreatedAt = createdAt.UTC()
@@ -3386,10 +5483,136 @@ func (r *postgresRepository) ListChatMessages(channelID string, limit int) ([]mo
 	if err := rows.Err(); err != nil {
 		return nil, fmt.Errorf("iterate chat messages: %w", err)
 	}
-
 	return messages, nil
 }
 
+func (r *postgresRepository) ChatAuthors(channelID string, userIDs []string) (map[string]chat.AuthorInfo, error) {
+	if r == nil || r.pool == nil {
+		return nil, ErrPostgresUnavailable
+	}
+
+	authors := make(map[string]chat.AuthorInfo, len(userIDs))
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		var exists bool
+		if err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM channels WHERE id = $1)", channelID).Scan(&exists); err != nil {
+			return fmt.Errorf("check channel %s: %w", channelID, err)
+		}
+		if !exists {
+			return notFound("channel", channelID)
+		}
+		if len(userIDs) == 0 {
+			return nil
+		}
+		rows, err := conn.Query(ctx, "SELECT u.id, u.display_name, u.roles, COALESCE(p.avatar_url, '') "+
+			"FROM users u LEFT JOIN profiles p ON p.user_id = u.id WHERE u.id = ANY($1)", userIDs)
+		if err != nil {
+			return fmt.Errorf("list chat authors: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			var (
+				info  chat.AuthorInfo
+				roles []string
+			)
+			if err := rows.Scan(&info.ID, &info.DisplayName, &roles, &info.AvatarURL); err != nil {
+				return fmt.Errorf("scan chat author: %w", err)
+			}
+			info.Roles = rolesFromDB(roles)
+			authors[info.ID] = info
+		}
+		if err := rows.Err(); err != nil {
+			return fmt.Errorf("iterate chat authors: %w", err)
+		}
+		rows.Close()
+		standings, err := querySubscriptionStandings(ctx, conn, channelID, userIDs, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		for id, standing := range standings {
+			if info, ok := authors[id]; ok {
+				info.Subscriber = standing.Active
+				info.SubscriberMonths = standing.Months
+				authors[id] = info
+			}
+		}
+		badges, err := globalBadgeSlugs(ctx, conn, userIDs)
+		if err != nil {
+			return err
+		}
+		for id, slugs := range badges {
+			if info, ok := authors[id]; ok {
+				info.GlobalBadges = slugs
+				authors[id] = info
+			}
+		}
+		moderatorRows, err := conn.Query(ctx, "SELECT user_id FROM channel_moderators WHERE channel_id = $1 AND user_id = ANY($2) AND (expires_at IS NULL OR expires_at > $3)", channelID, userIDs, r.grantTime())
+		if err != nil {
+			return fmt.Errorf("list chat author moderator grants: %w", err)
+		}
+		defer moderatorRows.Close()
+		for moderatorRows.Next() {
+			var id string
+			if err := moderatorRows.Scan(&id); err != nil {
+				return fmt.Errorf("scan chat author moderator grant: %w", err)
+			}
+			if info, ok := authors[id]; ok {
+				info.ChannelModerator = true
+				authors[id] = info
+			}
+		}
+		return moderatorRows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return authors, nil
+}
+
+// HasChatted answers from chat_messages_channel_user_created_at_idx, so the
+// live message path never scans a channel's transcript.
+func (r *postgresRepository) HasChatted(channelID, userID string) (bool, error) {
+	if r == nil || r.pool == nil {
+		return false, ErrPostgresUnavailable
+	}
+	ctx, cancel := r.acquireContext()
+	defer cancel()
+	var chatted bool
+	if err := r.pool.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM chat_messages WHERE channel_id = $1 AND user_id = $2)", channelID, userID).Scan(&chatted); err != nil {
+		return false, fmt.Errorf("check chat history: %w", err)
+	}
+	return chatted, nil
+}
+
+func (r *postgresRepository) ChatterStats(channelID string, since time.Time) (ChatterStats, error) {
+	if r == nil || r.pool == nil {
+		return ChatterStats{}, ErrPostgresUnavailable
+	}
+	var stats ChatterStats
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		var exists bool
+		if err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM channels WHERE id = $1)", channelID).Scan(&exists); err != nil {
+			return fmt.Errorf("check channel %s: %w", channelID, err)
+		}
+		if !exists {
+			return notFound("channel", channelID)
+		}
+		// Both the distinct scan and the per-chatter probe for earlier
+		// messages are served by the (channel_id, user_id, created_at) index.
+		err := conn.QueryRow(ctx, "SELECT COUNT(*), COUNT(*) FILTER (WHERE NOT EXISTS ("+
+			"SELECT 1 FROM chat_messages p WHERE p.channel_id = $1 AND p.user_id = c.user_id AND p.created_at < $2)) "+
+			"FROM (SELECT DISTINCT user_id FROM chat_messages WHERE channel_id = $1 AND created_at >= $2) c",
+			channelID, since.UTC()).Scan(&stats.UniqueChatters, &stats.FirstTimeChatters)
+		if err != nil {
+			return fmt.Errorf("count chatters: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return ChatterStats{}, err
+	}
+	return stats, nil
+}
+
 func (r *postgresRepository) ChatRestrictions() chat.RestrictionsSnapshot {
 	snapshot := chat.RestrictionsSnapshot{
 		Bans:            map[string]map[string]struct{}{},
@@ -3507,91 +5730,66 @@ func (r *postgresRepository) ChatTimeout(channelID, userID string) (time.Time, b
 	return expires.UTC(), true
 }
 
+// applyChatMessageEvent upserts a queued chat message along with its chat
+// sequence number. A message whose client message ID another message of the
+// same user in the channel still holds is a retried submission and is
+// dropped.
+func (r *postgresRepository) applyChatMessageEvent(msg chat.MessageEvent, seq int64) error {
+	if msg.ID == "" || msg.ChannelID == "" || msg.UserID == "" {
+		return fmt.Errorf("invalid message event")
+	}
+	return r.withTx(txSpec{Name: "apply chat message event", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		return applyChatMessageTx(ctx, tx, msg, seq)
+	})
+}
+
+func applyChatMessageTx(ctx context.Context, tx pgx.Tx, msg chat.MessageEvent, seq int64) error {
+	var clientParam any
+	if msg.ClientMessageID != "" {
+		clientParam = msg.ClientMessageID
+		if err := releaseExpiredChatClientMessageID(ctx, tx, msg.ChannelID, msg.UserID, msg.ClientMessageID, time.Now().UTC()); err != nil {
+			return err
+		}
+		var duplicate bool
+		if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM chat_messages WHERE channel_id = $1 AND user_id = $2 AND client_message_id = $3 AND id <> $4)", msg.ChannelID, msg.UserID, msg.ClientMessageID, msg.ID).Scan(&duplicate); err != nil {
+			return fmt.Errorf("check client message id: %w", err)
+		}
+		if duplicate {
+			return nil
+		}
+	}
+	if _, err := tx.Exec(ctx, "INSERT INTO chat_messages (id, channel_id, user_id, content, client_message_id, seq, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (id) DO UPDATE SET channel_id = EXCLUDED.channel_id, user_id = EXCLUDED.user_id, content = EXCLUDED.content, client_message_id = EXCLUDED.client_message_id, seq = EXCLUDED.seq, created_at = EXCLUDED.created_at", msg.ID, msg.ChannelID, msg.UserID, msg.Content, clientParam, seq, msg.CreatedAt.UTC()); err != nil {
+		return fmt.Errorf("persist chat message event: %w", err)
+	}
+	return nil
+}
+
 func (r *postgresRepository) ApplyChatEvent(evt chat.Event) error {
 	if r == nil || r.pool == nil {
 		return ErrPostgresUnavailable
 	}
+	if evt.Type == chat.EventTypeModeration {
+		if evt.Moderation == nil {
+			return fmt.Errorf("moderation payload missing")
+		}
+		return r.applyModerationEvent(*evt.Moderation, evt.Seq, evt.OccurredAt)
+	}
+
+	if evt.Type == chat.EventTypeMessage {
+		if evt.Message == nil {
+			return fmt.Errorf("message payload missing")
+		}
+		return r.applyChatMessageEvent(*evt.Message, evt.Seq)
+	}
 
 	return r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
 		switch evt.Type {
-		case chat.EventTypeMessage:
-			if evt.Message == nil {
-				return fmt.Errorf("message payload missing")
-			}
-			msg := evt.Message
-			if msg.ID == "" || msg.ChannelID == "" || msg.UserID == "" {
-				return fmt.Errorf("invalid message event")
-			}
-			if _, err := conn.Exec(ctx, "INSERT INTO chat_messages (id, channel_id, user_id, content, created_at) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (id) DO UPDATE SET channel_id = EXCLUDED.channel_id, user_id = EXCLUDED.user_id, content = EXCLUDED.content, created_at = EXCLUDED.created_at", msg.ID, msg.ChannelID, msg.UserID, msg.Content, msg.CreatedAt.UTC()); err != nil {
-				return fmt.Errorf("persist chat message event: %w", err)
-			}
-			return nil
-		case chat.EventTypeModeration:
-			if evt.Moderation == nil {
-				return fmt.Errorf("moderation payload missing")
-			}
-			mod := evt.Moderation
-			issued := evt.OccurredAt.UTC()
-			if issued.IsZero() {
-				issued = time.Now().UTC()
-			}
-			actor := strings.TrimSpace(mod.ActorID)
-			var actorParam any
-			if actor != "" {
-				actorParam = actor
-			}
-			reason := strings.TrimSpace(mod.Reason)
-			switch mod.Action {
-			case chat.ModerationActionBan:
-				if _, err := conn.Exec(ctx, "INSERT INTO chat_bans (channel_id, user_id, actor_id, reason, issued_at) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (channel_id, user_id) DO UPDATE SET actor_id = EXCLUDED.actor_id, reason = EXCLUDED.reason, issued_at = EXCLUDED.issued_at", mod.ChannelID, mod.TargetID, actorParam, reason, issued); err != nil {
-					return fmt.Errorf("apply ban event: %w", err)
-				}
-				return nil
-			case chat.ModerationActionUnban:
-				if _, err := conn.Exec(ctx, "DELETE FROM chat_bans WHERE channel_id = $1 AND user_id = $2", mod.ChannelID, mod.TargetID); err != nil {
-					return fmt.Errorf("apply unban event: %w", err)
-				}
-				return nil
-			case chat.ModerationActionTimeout:
-				if mod.ExpiresAt == nil {
-					return nil
-				}
-				expires := mod.ExpiresAt.UTC()
-				if _, err := conn.Exec(ctx, "INSERT INTO chat_timeouts (channel_id, user_id, actor_id, reason, issued_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (channel_id, user_id) DO UPDATE SET actor_id = EXCLUDED.actor_id, reason = EXCLUDED.reason, issued_at = EXCLUDED.issued_at, expires_at = EXCLUDED.expires_at", mod.ChannelID, mod.TargetID, actorParam, reason, issued, expires); err != nil {
-					return fmt.Errorf("apply timeout event: %w", err)
-				}
-				return nil
-			case chat.ModerationActionRemoveTimeout:
-				if _, err := conn.Exec(ctx, "DELETE FROM chat_timeouts WHERE channel_id = $1 AND user_id = $2", mod.ChannelID, mod.TargetID); err != nil {
-					return fmt.Errorf("apply remove timeout event: %w", err)
-				}
-				return nil
-			default:
-				return fmt.Errorf("unsupported moderation action %q", mod.Action)
-			}
 		case chat.EventTypeReport:
 			if evt.Report == nil {
 				return fmt.Errorf("report payload missing")
 			}
-			rep := evt.Report
-			if strings.TrimSpace(rep.ID) == "" {
-				return fmt.Errorf("report id missing")
-			}
-			status := strings.ToLower(strings.TrimSpace(rep.Status))
-			if status == "" {
-				status = "open"
-			}
-			var messageParam any
-			if strings.TrimSpace(rep.MessageID) != "" {
-				messageParam = strings.TrimSpace(rep.MessageID)
-			}
-			var evidenceParam any
-			if strings.TrimSpace(rep.EvidenceURL) != "" {
-				evidenceParam = strings.TrimSpace(rep.EvidenceURL)
-			}
-			if _, err := conn.Exec(ctx, "INSERT INTO chat_reports (id, channel_id, reporter_id, target_id, reason, message_id, evidence_url, status, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9) ON CONFLICT (id) DO UPDATE SET channel_id = EXCLUDED.channel_id, reporter_id = EXCLUDED.reporter_id, target_id = EXCLUDED.target_id, reason = EXCLUDED.reason, message_id = EXCLUDED.message_id, evidence_url = EXCLUDED.evidence_url, status = EXCLUDED.status, created_at = EXCLUDED.created_at", rep.ID, rep.ChannelID, rep.ReporterID, rep.TargetID, rep.Reason, messageParam, evidenceParam, status, rep.CreatedAt.UTC()); err != nil {
-				return fmt.Errorf("apply report event: %w", err)
-			}
+			return applyChatReportEvent(ctx, conn, *evt.Report)
+		case chat.EventTypeGift, chat.EventTypeMarker, chat.EventTypePin, chat.EventTypeModerator:
 			return nil
 		default:
 			return fmt.Errorf("unsupported chat event %q", evt.Type)
@@ -3599,54 +5797,146 @@ func (r *postgresRepository) ApplyChatEvent(evt chat.Event) error {
 	})
 }
 
-func (r *postgresRepository) ListChatRestrictions(channelID string) []models.ChatRestriction {
-	if r == nil || r.pool == nil {
+// chatEventExecer runs the statements of a chat event on a pooled
+// connection or inside a transaction.
+type chatEventExecer interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+}
+
+func applyChatReportEvent(ctx context.Context, conn chatEventExecer, rep chat.ReportEvent) error {
+	if strings.TrimSpace(rep.ID) == "" {
+		return fmt.Errorf("report id missing")
+	}
+	status := strings.ToLower(strings.TrimSpace(rep.Status))
+	if status == "" {
+		status = "open"
+	}
+	var messageParam any
+	if strings.TrimSpace(rep.MessageID) != "" {
+		messageParam = strings.TrimSpace(rep.MessageID)
+	}
+	var evidenceParam any
+	if strings.TrimSpace(rep.EvidenceURL) != "" {
+		evidenceParam = strings.TrimSpace(rep.EvidenceURL)
+	}
+	if _, err := conn.Exec(ctx, "INSERT INTO chat_reports (id, channel_id, reporter_id, target_id, subject_id, reason, message_id, evidence_url, status, created_at) VALUES ($1, $2, $3, $4, $5, $6, $5, $7, $8, $9) ON CONFLICT (id) DO UPDATE SET channel_id = EXCLUDED.channel_id, reporter_id = EXCLUDED.reporter_id, target_id = EXCLUDED.target_id, subject_id = EXCLUDED.subject_id, reason = EXCLUDED.reason, message_id = EXCLUDED.message_id, evidence_url = EXCLUDED.evidence_url, status = EXCLUDED.status, created_at = EXCLUDED.created_at", rep.ID, rep.ChannelID, rep.ReporterID, rep.TargetID, messageParam, rep.Reason, evidenceParam, status, rep.CreatedAt.UTC()); err != nil {
+		return fmt.Errorf("apply report event: %w", err)
+	}
+	return nil
+}
+
+// applyModerationEvent updates the channel's restrictions, or deletes the
+// named message, and appends the moderation log entry with the event's
+// sequence number in one transaction, so a redelivered event neither
+// half-applies nor logs twice.
+func (r *postgresRepository) applyModerationEvent(mod chat.ModerationEvent, seq int64, occurredAt time.Time) error {
+	return r.withTx(txSpec{Name: "apply moderation event", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		return applyModerationEventTx(ctx, tx, mod, seq, occurredAt)
+	})
+}
+
+func applyModerationEventTx(ctx context.Context, tx pgx.Tx, mod chat.ModerationEvent, seq int64, occurredAt time.Time) error {
+	issued := occurredAt.UTC()
+	if issued.IsZero() {
+		issued = time.Now().UTC()
+	}
+	actor := strings.TrimSpace(mod.ActorID)
+	var actorParam any
+	if actor != "" {
+		actorParam = actor
+	}
+	reason := strings.TrimSpace(mod.Reason)
+	switch mod.Action {
+	case chat.ModerationActionBan:
+		if _, err := tx.Exec(ctx, "INSERT INTO chat_bans (channel_id, user_id, actor_id, reason, issued_at) VALUES ($1, $2, $3, $4, $5) ON CONFLICT (channel_id, user_id) DO UPDATE SET actor_id = EXCLUDED.actor_id, reason = EXCLUDED.reason, issued_at = EXCLUDED.issued_at", mod.ChannelID, mod.TargetID, actorParam, reason, issued); err != nil {
+			return fmt.Errorf("apply ban event: %w", err)
+		}
+	case chat.ModerationActionUnban:
+		if _, err := tx.Exec(ctx, "DELETE FROM chat_bans WHERE channel_id = $1 AND user_id = $2", mod.ChannelID, mod.TargetID); err != nil {
+			return fmt.Errorf("apply unban event: %w", err)
+		}
+	case chat.ModerationActionTimeout:
+		if mod.ExpiresAt == nil {
+			return nil
+		}
+		expires := mod.ExpiresAt.UTC()
+		if _, err := tx.Exec(ctx, "INSERT INTO chat_timeouts (channel_id, user_id, actor_id, reason, issued_at, expires_at) VALUES ($1, $2, $3, $4, $5, $6) ON CONFLICT (channel_id, user_id) DO UPDATE SET actor_id = EXCLUDED.actor_id, reason = EXCLUDED.reason, issued_at = EXCLUDED.issued_at, expires_at = EXCLUDED.expires_at", mod.ChannelID, mod.TargetID, actorParam, reason, issued, expires); err != nil {
+			return fmt.Errorf("apply timeout event: %w", err)
+		}
+	case chat.ModerationActionRemoveTimeout:
+		if _, err := tx.Exec(ctx, "DELETE FROM chat_timeouts WHERE channel_id = $1 AND user_id = $2", mod.ChannelID, mod.TargetID); err != nil {
+			return fmt.Errorf("apply remove timeout event: %w", err)
+		}
+	case chat.ModerationActionDeleteMessage:
+		message := models.ChatMessage{ID: mod.MessageID, ChannelID: mod.ChannelID}
+		err := tx.QueryRow(ctx, "DELETE FROM chat_messages WHERE id = $1 AND channel_id = $2 RETURNING user_id, content", mod.MessageID, mod.ChannelID).Scan(&message.UserID, &message.Content)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		if err != nil {
+			return fmt.Errorf("apply delete message event: %w", err)
+		}
+		action := deletedMessageAction(message, actor, issued)
+		action.ID = mod.ID
+		action.Seq = seq
+		return insertModerationAction(ctx, tx, action)
+	default:
+		return fmt.Errorf("unsupported moderation action %q", mod.Action)
+	}
+	action, ok := moderationActionFromEvent(mod, issued)
+	if !ok {
 		return nil
 	}
+	action.Seq = seq
+	return insertModerationAction(ctx, tx, action)
+}
+
+func (r *postgresRepository) ListChatRestrictions(channelID string) ([]models.ChatRestriction, error) {
+	if r == nil || r.pool == nil {
+		return nil, ErrPostgresUnavailable
+	}
 	restrictions := make([]models.ChatRestriction, 0)
-	aborted := false
 	now := time.Now().UTC()
 	if err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
 		banRows, err := conn.Query(ctx, "SELECT user_id, actor_id, reason, issued_at FROM chat_bans WHERE channel_id = $1", channelID)
-		if err == nil {
-			defer banRows.Close()
-			for banRows.Next() {
-				var (
-					userID string
-					actor  pgtype.Text
-					reason string
-					issued time.Time
-				)
-				if err := banRows.Scan(&userID, &actor, &reason, &issued); err != nil {
-					aborted = true
-					return nil
-				}
-				restriction := models.ChatRestriction{
-					ID:        fmt.Sprintf("ban:%s:%s", channelID, userID),
-					Type:      "ban",
-					ChannelID: channelID,
-					TargetID:  userID,
-					Reason:    reason,
-					IssuedAt:  issued.UTC(),
-				}
-				if actor.Valid {
-					restriction.ActorID = actor.String
-				}
-				restrictions = append(restrictions, restriction)
+		if err != nil {
+			return fmt.Errorf("list chat bans: %w", err)
+		}
+		defer banRows.Close()
+		for banRows.Next() {
+			var (
+				userID string
+				actor  pgtype.Text
+				reason string
+				issued time.Time
+			)
+			if err := banRows.Scan(&userID, &actor, &reason, &issued); err != nil {
+				return fmt.Errorf("scan chat ban: %w", err)
 			}
-			if err := banRows.Err(); err != nil {
-				aborted = true
-				return nil
+			restriction := models.ChatRestriction{
+				ID:        fmt.Sprintf("ban:%s:%s", channelID, userID),
+				Type:      "ban",
+				ChannelID: channelID,
+				TargetID:  userID,
+				Reason:    reason,
+				IssuedAt:  issued.UTC(),
+			}
+			if actor.Valid {
+				restriction.ActorID = actor.String
 			}
+			restrictions = append(restrictions, restriction)
+		}
+		if err := banRows.Err(); err != nil {
+			return err
 		}
 
 		if _, err := conn.Exec(ctx, "DELETE FROM chat_timeouts WHERE channel_id = $1 AND expires_at <= $2", channelID, now); err != nil {
-			return nil
+			return fmt.Errorf("prune expired chat timeouts: %w", err)
 		}
 
 		timeoutRows, err := conn.Query(ctx, "SELECT user_id, actor_id, reason, issued_at, expires_at FROM chat_timeouts WHERE channel_id = $1 AND expires_at > $2", channelID, now)
 		if err != nil {
-			return nil
+			return fmt.Errorf("list chat timeouts: %w", err)
 		}
 		defer timeoutRows.Close()
 		for timeoutRows.Next() {
@@ -3658,8 +5948,7 @@ func (r *postgresRepository) ListChatRestrictions(channelID string) []models.Cha
 				expires time.Time
 			)
 			if err := timeoutRows.Scan(&userID, &actor, &reason, &issued, &expires); err != nil {
-				aborted = true
-				return nil
+				return fmt.Errorf("scan chat timeout: %w", err)
 			}
 			expiry := expires.UTC()
 			restriction := models.ChatRestriction{
@@ -3676,16 +5965,9 @@ func (r *postgresRepository) ListChatRestrictions(channelID string) []models.Cha
 			}
 			restrictions = append(restrictions, restriction)
 		}
-		if err := timeoutRows.Err(); err != nil {
-			aborted = true
-			return nil
-		}
-		return nil
+		return timeoutRows.Err()
 	}); err != nil {
-		return nil
-	}
-	if aborted {
-		return restrictions
+		return nil, err
 	}
 	sort.Slice(restrictions, func(i, j int) bool {
 		if restrictions[i].IssuedAt.Equal(restrictions[j].IssuedAt) {
@@ -3693,265 +5975,313 @@ func (r *postgresRepository) ListChatRestrictions(channelID string) []models.Cha
 		}
 		return restrictions[i].IssuedAt.After(restrictions[j].IssuedAt)
 	})
-	return restrictions
+	return restrictions, nil
 }
 func (r *postgresRepository) CreateChatReport(channelID, reporterID, targetID, reason, messageID, evidenceURL string) (models.ChatReport, error) {
 	if r == nil || r.pool == nil {
 		return models.ChatReport{}, ErrPostgresUnavailable
 	}
 
-	trimmedReason := strings.TrimSpace(reason)
-	if trimmedReason == "" {
-		return models.ChatReport{}, fmt.Errorf("reason is required")
-	}
+	trimmedReason := strings.TrimSpace(reason)
+	if trimmedReason == "" {
+		return models.ChatReport{}, fmt.Errorf("reason is required")
+	}
+
+	id, err := generateID()
+	if err != nil {
+		return models.ChatReport{}, err
+	}
+
+	trimmedMessageID := strings.TrimSpace(messageID)
+	report := models.ChatReport{}
+
+	createErr := r.withTx(txSpec{Name: "create chat report", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		if err := ensureChannelExists(ctx, tx, channelID); err != nil {
+			return err
+		}
+		if err := ensureUserExists(ctx, tx, reporterID); err != nil {
+			return err
+		}
+		if err := ensureUserExists(ctx, tx, targetID); err != nil {
+			return err
+		}
+
+		created := models.ChatReport{
+			ID:          id,
+			ChannelID:   channelID,
+			ReporterID:  reporterID,
+			TargetID:    targetID,
+			SubjectType: models.ReportSubjectChatMessage,
+			Category:    models.ReportCategoryOther,
+			Reason:      trimmedReason,
+			EvidenceURL: strings.TrimSpace(evidenceURL),
+			Status:      ChatReportStatusOpen,
+			CreatedAt:   time.Now().UTC(),
+		}
+		if trimmedMessageID != "" {
+			var messageExists bool
+			if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM chat_messages WHERE id = $1 AND channel_id = $2)", trimmedMessageID, channelID).Scan(&messageExists); err != nil {
+				return fmt.Errorf("check chat message %s: %w", trimmedMessageID, err)
+			}
+			if messageExists {
+				created.MessageID = trimmedMessageID
+				created.SubjectID = trimmedMessageID
+			}
+		}
+		if err := insertChatReport(ctx, tx, created); err != nil {
+			return err
+		}
+		report = created
+		return nil
+	})
+	if createErr != nil {
+		return models.ChatReport{}, createErr
+	}
+	return report, nil
+}
+
+func (r *postgresRepository) ListChatReports(channelID string, includeResolved bool) ([]models.ChatReport, error) {
+	return r.ListReports(ReportFilter{ChannelID: channelID, IncludeResolved: includeResolved})
+}
+
+func (r *postgresRepository) ResolveChatReport(reportID, resolverID, resolution string) (models.ChatReport, error) {
+	if r == nil || r.pool == nil {
+		return models.ChatReport{}, ErrPostgresUnavailable
+	}
+
+	resolved := models.ChatReport{}
+	err := r.withTx(txSpec{Name: "resolve chat report", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		current, err := scanChatReport(tx.QueryRow(ctx, "SELECT "+chatReportColumns+" FROM chat_reports WHERE id = $1", reportID))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return notFound("report", reportID)
+			}
+			return fmt.Errorf("load chat report %s: %w", reportID, err)
+		}
+		resolved = current
+		if strings.EqualFold(resolved.Status, "resolved") {
+			return nil
+		}
+
+		if err := ensureUserExists(ctx, tx, resolverID); err != nil {
+			return err
+		}
+
+		trimmed := strings.TrimSpace(resolution)
+		if trimmed == "" {
+			trimmed = "resolved"
+		}
+		now := time.Now().UTC()
+
+		updated, err := scanChatReport(tx.QueryRow(ctx, "UPDATE chat_reports SET status = 'resolved', resolution = $1, resolver_id = $2, resolved_at = $3 WHERE id = $4 RETURNING "+chatReportColumns, trimmed, resolverID, now, reportID))
+		if err != nil {
+			return fmt.Errorf("update chat report %s: %w", reportID, err)
+		}
+		resolved = updated
 
-	id, err := generateID()
+		return insertModerationAction(ctx, tx, resolvedReportAction(resolved))
+	})
 	if err != nil {
 		return models.ChatReport{}, err
 	}
+	return resolved, nil
+}
 
-	trimmedMessageID := strings.TrimSpace(messageID)
-	trimmedEvidence := strings.TrimSpace(evidenceURL)
-	now := time.Now().UTC()
-	report := models.ChatReport{}
+const chatAppealColumns = "id, channel_id, user_id, restriction, message, status, resolver_id, resolution_note, created_at, resolved_at"
 
-	createErr := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
-		if err != nil {
-			return fmt.Errorf("begin create chat report tx: %w", err)
-		}
-		defer rollbackTx(ctx, tx)
+func scanChatAppeal(row pgx.Row) (models.ChatAppeal, error) {
+	var (
+		appeal     models.ChatAppeal
+		resolver   pgtype.Text
+		resolvedAt pgtype.Timestamptz
+	)
+	if err := row.Scan(&appeal.ID, &appeal.ChannelID, &appeal.UserID, &appeal.Restriction, &appeal.Message, &appeal.Status, &resolver, &appeal.ResolutionNote, &appeal.CreatedAt, &resolvedAt); err != nil {
+		return models.ChatAppeal{}, err
+	}
+	if resolver.Valid {
+		appeal.ResolverID = resolver.String
+	}
+	appeal.CreatedAt = appeal.CreatedAt.UTC()
+	if resolvedAt.Valid {
+		ts := resolvedAt.Time.UTC()
+		appeal.ResolvedAt = &ts
+	}
+	return appeal, nil
+}
 
+// CreateChatAppeal files an appeal by a banned or timed-out user. A partial
+// unique index keeps one open appeal per user and channel.
+func (r *postgresRepository) CreateChatAppeal(channelID, userID, message string) (models.ChatAppeal, error) {
+	if r == nil || r.pool == nil {
+		return models.ChatAppeal{}, ErrPostgresUnavailable
+	}
+	var appeal models.ChatAppeal
+	err := r.withTx(txSpec{Name: "chat appeal", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
 		if err := ensureChannelExists(ctx, tx, channelID); err != nil {
 			return err
 		}
-		if err := ensureUserExists(ctx, tx, reporterID); err != nil {
+		if err := ensureUserExists(ctx, tx, userID); err != nil {
 			return err
 		}
-		if err := ensureUserExists(ctx, tx, targetID); err != nil {
-			return err
+		now := time.Now().UTC()
+		var banned, timedOut bool
+		if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM chat_bans WHERE channel_id = $1 AND user_id = $2), EXISTS (SELECT 1 FROM chat_timeouts WHERE channel_id = $1 AND user_id = $2 AND expires_at > $3)", channelID, userID, now).Scan(&banned, &timedOut); err != nil {
+			return fmt.Errorf("check chat restriction: %w", err)
 		}
-
-		var messageParam any
-		if trimmedMessageID != "" {
-			var messageExists bool
-			if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM chat_messages WHERE id = $1 AND channel_id = $2)", trimmedMessageID, channelID).Scan(&messageExists); err != nil {
-				return fmt.Errorf("check chat message %s: %w", trimmedMessageID, err)
-			}
-			if messageExists {
-				messageParam = trimmedMessageID
-			}
+		var restriction string
+		switch {
+		case banned:
+			restriction = "ban"
+		case timedOut:
+			restriction = "timeout"
+		default:
+			return ErrNotChatRestricted
 		}
-		var evidenceParam any
-		if trimmedEvidence != "" {
-			evidenceParam = trimmedEvidence
+		created, err := newChatAppeal(channelID, userID, restriction, message, now)
+		if err != nil {
+			return err
 		}
-
-		status := "open"
-		if _, err := tx.Exec(ctx, "INSERT INTO chat_reports (id, channel_id, reporter_id, target_id, reason, message_id, evidence_url, status, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9)", id, channelID, reporterID, targetID, trimmedReason, messageParam, evidenceParam, status, now); err != nil {
-			return fmt.Errorf("insert chat report: %w", err)
+		tag, err := tx.Exec(ctx, "INSERT INTO chat_appeals (id, channel_id, user_id, restriction, message, status, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (channel_id, user_id) WHERE status = 'open' DO NOTHING",
+			created.ID,
+			created.ChannelID,
+			created.UserID,
+			created.Restriction,
+			created.Message,
+			created.Status,
+			created.CreatedAt,
+		)
+		if err != nil {
+			return fmt.Errorf("insert chat appeal: %w", err)
 		}
-
-		if err := tx.Commit(ctx); err != nil {
-			return fmt.Errorf("commit chat report: %w", err)
+		if tag.RowsAffected() == 0 {
+			return ErrChatAppealOpen
 		}
+		appeal = created
+		return nil
+	})
+	if err != nil {
+		return models.ChatAppeal{}, err
+	}
+	return appeal, nil
+}
 
-		report = models.ChatReport{
-			ID:          id,
-			ChannelID:   channelID,
-			ReporterID:  reporterID,
-			TargetID:    targetID,
-			Reason:      trimmedReason,
-			EvidenceURL: trimmedEvidence,
-			Status:      status,
-			CreatedAt:   now,
+// GetChatAppeal returns one of the channel's appeals.
+func (r *postgresRepository) GetChatAppeal(channelID, appealID string) (models.ChatAppeal, error) {
+	if r == nil || r.pool == nil {
+		return models.ChatAppeal{}, ErrPostgresUnavailable
+	}
+	var appeal models.ChatAppeal
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		var err error
+		appeal, err = scanChatAppeal(conn.QueryRow(ctx, "SELECT "+chatAppealColumns+" FROM chat_appeals WHERE id = $1 AND channel_id = $2", appealID, channelID))
+		if errors.Is(err, pgx.ErrNoRows) {
+			return ErrChatAppealNotFound
 		}
-		if messageParam != nil {
-			report.MessageID = trimmedMessageID
+		if err != nil {
+			return fmt.Errorf("load chat appeal %s: %w", appealID, err)
 		}
 		return nil
 	})
-	if createErr != nil {
-		return models.ChatReport{}, createErr
+	if err != nil {
+		return models.ChatAppeal{}, err
 	}
-	return report, nil
+	return appeal, nil
 }
 
-func (r *postgresRepository) ListChatReports(channelID string, includeResolved bool) ([]models.ChatReport, error) {
+// ListChatAppeals returns the channel's appeals newest first, only the open
+// ones unless includeResolved is set.
+func (r *postgresRepository) ListChatAppeals(channelID string, includeResolved bool) ([]models.ChatAppeal, error) {
 	if r == nil || r.pool == nil {
 		return nil, ErrPostgresUnavailable
 	}
-	reports := make([]models.ChatReport, 0)
+	appeals := make([]models.ChatAppeal, 0)
 	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
 		var exists bool
 		if err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM channels WHERE id = $1)", channelID).Scan(&exists); err != nil {
 			return fmt.Errorf("check channel %s: %w", channelID, err)
 		}
 		if !exists {
-			return fmt.Errorf("channel %s not found", channelID)
+			return notFound("channel", channelID)
 		}
-
-		query := "SELECT id, channel_id, reporter_id, target_id, reason, message_id, evidence_url, status, resolution, resolver_id, created_at, resolved_at FROM chat_reports WHERE channel_id = $1"
-		args := []any{channelID}
+		query := "SELECT " + chatAppealColumns + " FROM chat_appeals WHERE channel_id = $1"
 		if !includeResolved {
-			query += " AND LOWER(status) <> 'resolved'"
+			query += " AND status = 'open'"
 		}
-		query += " ORDER BY created_at DESC, id ASC"
-
-		rows, err := conn.Query(ctx, query, args...)
+		rows, err := conn.Query(ctx, query+" ORDER BY created_at DESC, id", channelID)
 		if err != nil {
-			return fmt.Errorf("list chat reports: %w", err)
+			return fmt.Errorf("list chat appeals: %w", err)
 		}
 		defer rows.Close()
-
 		for rows.Next() {
-			var (
-				report      models.ChatReport
-				messageID   pgtype.Text
-				evidenceURL pgtype.Text
-				status      string
-				resolution  pgtype.Text
-				resolverID  pgtype.Text
-				createdAt   time.Time
-				resolvedAt  pgtype.Timestamptz
-			)
-			if err := rows.Scan(&report.ID, &report.ChannelID, &report.ReporterID, &report.TargetID, &report.Reason, &messageID, &evidenceURL, &status, &resolution, &resolverID, &createdAt, &resolvedAt); err != nil {
-				return fmt.Errorf("scan chat report: %w", err)
-			}
-			if messageID.Valid {
-				report.MessageID = messageID.String
-			}
-			if evidenceURL.Valid {
-				report.EvidenceURL = evidenceURL.String
-			}
-			report.Status = strings.ToLower(status)
-			if resolution.Valid {
-				report.Resolution = resolution.String
-			}
-			if resolverID.Valid {
-				report.ResolverID = resolverID.String
-			}
-			report.CreatedAt = createdAt.UTC()
-			if resolvedAt.Valid {
-				ts := resolvedAt.Time.UTC()
-				report.ResolvedAt = &ts
+			appeal, err := scanChatAppeal(rows)
+			if err != nil {
+				return fmt.Errorf("scan chat appeal: %w", err)
 			}
-			reports = append(reports, report)
+			appeals = append(appeals, appeal)
 		}
 		if err := rows.Err(); err != nil {
-			return fmt.Errorf("iterate chat reports: %w", err)
+			return fmt.Errorf("iterate chat appeals: %w", err)
 		}
 		return nil
 	})
 	if err != nil {
 		return nil, err
 	}
-	return reports, nil
+	return appeals, nil
 }
-func (r *postgresRepository) ResolveChatReport(reportID, resolverID, resolution string) (models.ChatReport, error) {
+
+// LatestChatAppeal returns the user's most recent appeal in the channel.
+func (r *postgresRepository) LatestChatAppeal(channelID, userID string) (models.ChatAppeal, bool) {
 	if r == nil || r.pool == nil {
-		return models.ChatReport{}, ErrPostgresUnavailable
+		return models.ChatAppeal{}, false
+	}
+	ctx, cancel := r.acquireContext()
+	defer cancel()
+	appeal, err := scanChatAppeal(r.pool.QueryRow(ctx, "SELECT "+chatAppealColumns+" FROM chat_appeals WHERE channel_id = $1 AND user_id = $2 ORDER BY created_at DESC, id LIMIT 1", channelID, userID))
+	if err != nil {
+		return models.ChatAppeal{}, false
 	}
+	return appeal, true
+}
 
-	resolved := models.ChatReport{}
-	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
+// ResolveChatAppeal closes an open appeal. Callers lift the restriction of
+// an accepted appeal through the chat moderation path.
+func (r *postgresRepository) ResolveChatAppeal(channelID, appealID, resolverID string, accept bool, note string) (models.ChatAppeal, error) {
+	if r == nil || r.pool == nil {
+		return models.ChatAppeal{}, ErrPostgresUnavailable
+	}
+	var appeal models.ChatAppeal
+	err := r.withTx(txSpec{Name: "resolve chat appeal", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		current, err := scanChatAppeal(tx.QueryRow(ctx, "SELECT "+chatAppealColumns+" FROM chat_appeals WHERE id = $1 AND channel_id = $2 FOR UPDATE", appealID, channelID))
 		if err != nil {
-			return fmt.Errorf("begin resolve chat report tx: %w", err)
-		}
-		defer rollbackTx(ctx, tx)
-
-		var (
-			messageID      pgtype.Text
-			evidenceURL    pgtype.Text
-			status         string
-			resolutionText pgtype.Text
-			resolver       pgtype.Text
-			createdAt      time.Time
-			resolvedAt     pgtype.Timestamptz
-		)
-		row := tx.QueryRow(ctx, "SELECT id, channel_id, reporter_id, target_id, reason, message_id, evidence_url, status, resolution, resolver_id, created_at, resolved_at FROM chat_reports WHERE id = $1", reportID)
-		if err := row.Scan(&resolved.ID, &resolved.ChannelID, &resolved.ReporterID, &resolved.TargetID, &resolved.Reason, &messageID, &evidenceURL, &status, &resolutionText, &resolver, &createdAt, &resolvedAt); err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
-				return fmt.Errorf("report %s not found", reportID)
+				return ErrChatAppealNotFound
 			}
-			return fmt.Errorf("load chat report %s: %w", reportID, err)
-		}
-		if messageID.Valid {
-			resolved.MessageID = messageID.String
-		}
-		if evidenceURL.Valid {
-			resolved.EvidenceURL = evidenceURL.String
-		}
-		if resolutionText.Valid {
-			resolved.Resolution = resolutionText.String
-		}
-		if resolver.Valid {
-			resolved.ResolverID = resolver.String
-		}
-		resolved.Status = strings.ToLower(status)
-		resolved.CreatedAt = createdAt.UTC()
-		if resolvedAt.Valid {
-			ts := resolvedAt.Time.UTC()
-			resolved.ResolvedAt = &ts
+			return fmt.Errorf("load chat appeal %s: %w", appealID, err)
 		}
-
-		if strings.EqualFold(resolved.Status, "resolved") {
-			return nil
-		}
-
 		if err := ensureUserExists(ctx, tx, resolverID); err != nil {
 			return err
 		}
-
-		trimmed := strings.TrimSpace(resolution)
-		if trimmed == "" {
-			trimmed = "resolved"
-		}
-		now := time.Now().UTC()
-
-		updateRow := tx.QueryRow(ctx, "UPDATE chat_reports SET status = 'resolved', resolution = $1, resolver_id = $2, resolved_at = $3 WHERE id = $4 RETURNING id, channel_id, reporter_id, target_id, reason, message_id, evidence_url, status, resolution, resolver_id, created_at, resolved_at", trimmed, resolverID, now, reportID)
-		if err := updateRow.Scan(&resolved.ID, &resolved.ChannelID, &resolved.ReporterID, &resolved.TargetID, &resolved.Reason, &messageID, &evidenceURL, &status, &resolutionText, &resolver, &createdAt, &resolvedAt); err != nil {
-			return fmt.Errorf("update chat report %s: %w", reportID, err)
-		}
-		if messageID.Valid {
-			resolved.MessageID = messageID.String
-		} else {
-			resolved.MessageID = ""
-		}
-		if evidenceURL.Valid {
-			resolved.EvidenceURL = evidenceURL.String
-		} else {
-			resolved.EvidenceURL = ""
-		}
-		resolved.Status = strings.ToLower(status)
-		if resolutionText.Valid {
-			resolved.Resolution = resolutionText.String
-		} else {
-			resolved.Resolution = ""
-		}
-		if resolver.Valid {
-			resolved.ResolverID = resolver.String
-		} else {
-			resolved.ResolverID = ""
-		}
-		resolved.CreatedAt = createdAt.UTC()
-		if resolvedAt.Valid {
-			ts := resolvedAt.Time.UTC()
-			resolved.ResolvedAt = &ts
-		} else {
-			resolved.ResolvedAt = nil
+		resolved, err := resolveChatAppeal(current, resolverID, accept, note, time.Now().UTC())
+		if err != nil {
+			return err
 		}
-
-		if err := tx.Commit(ctx); err != nil {
-			return fmt.Errorf("commit resolve chat report: %w", err)
+		if _, err := tx.Exec(ctx, "UPDATE chat_appeals SET status = $1, resolver_id = $2, resolution_note = $3, resolved_at = $4 WHERE id = $5",
+			resolved.Status,
+			resolved.ResolverID,
+			resolved.ResolutionNote,
+			*resolved.ResolvedAt,
+			resolved.ID,
+		); err != nil {
+			return fmt.Errorf("update chat appeal %s: %w", appealID, err)
 		}
+		appeal = resolved
 		return nil
 	})
 	if err != nil {
-		return models.ChatReport{}, err
+		return models.ChatAppeal{}, err
 	}
-	return resolved, nil
+	return appeal, nil
 }
 
 func (r *postgresRepository) CreateTip(params CreateTipParams) (models.Tip, error) {
@@ -3971,7 +6301,7 @@ func (r *postgresRepository) CreateTip(params CreateTipParams) (models.Tip, erro
 
 	provider := strings.ToLower(strings.TrimSpace(params.Provider))
 	if provider == "" {
-		return models.Tip{}, fmt.Errorf("provider is required")
+		return models.Tip{}, invalid("provider", "provider is required")
 	}
 
 	reference := strings.TrimSpace(params.Reference)
@@ -3999,13 +6329,7 @@ func (r *postgresRepository) CreateTip(params CreateTipParams) (models.Tip, erro
 
 	now := time.Now().UTC()
 	var tip models.Tip
-	saveErr := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
-		if err != nil {
-			return fmt.Errorf("begin create tip tx: %w", err)
-		}
-		defer rollbackTx(ctx, tx)
-
+	saveErr := r.withTx(txSpec{Name: "create tip", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
 		if err := ensureChannelExists(ctx, tx, params.ChannelID); err != nil {
 			return err
 		}
@@ -4018,7 +6342,7 @@ func (r *postgresRepository) CreateTip(params CreateTipParams) (models.Tip, erro
 			return fmt.Errorf("check tip reference: %w", err)
 		}
 		if exists {
-			return fmt.Errorf("tip reference %s/%s already exists", provider, reference)
+			return conflict("reference", "tip reference %s/%s already exists", provider, reference)
 		}
 
 		var createdAt time.Time
@@ -4026,10 +6350,6 @@ func (r *postgresRepository) CreateTip(params CreateTipParams) (models.Tip, erro
 			return fmt.Errorf("insert tip: %w", err)
 		}
 
-		if err := tx.Commit(ctx); err != nil {
-			return fmt.Errorf("commit create tip: %w", err)
-		}
-
 		tip = models.Tip{
 			ID:            id,
 			ChannelID:     params.ChannelID,
@@ -4058,13 +6378,8 @@ func (r *postgresRepository) ListTips(channelID string, limit int) ([]models.Tip
 	}
 
 	tips := make([]models.Tip, 0)
-	listErr := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		tx, err := conn.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
-		if err != nil {
-			return fmt.Errorf("begin list tips tx: %w", err)
-		}
-		defer rollbackTx(ctx, tx)
-
+	listErr := r.withTx(txSpec{Name: "list tips", RetrySafe: true, Options: pgx.TxOptions{AccessMode: pgx.ReadOnly}}, func(ctx context.Context, tx pgx.Tx) error {
+		tips = tips[:0]
 		if err := ensureChannelExists(ctx, tx, channelID); err != nil {
 			return err
 		}
@@ -4104,10 +6419,6 @@ func (r *postgresRepository) ListTips(channelID string, limit int) ([]models.Tip
 			return err
 		}
 
-		if err := tx.Commit(ctx); err != nil {
-			return fmt.Errorf("commit list tips: %w", err)
-		}
-
 		return nil
 	})
 	if listErr != nil {
@@ -4143,7 +6454,7 @@ func (r *postgresRepository) CreateSubscription(params CreateSubscriptionParams)
 
 	provider := strings.ToLower(strings.TrimSpace(params.Provider))
 	if provider == "" {
-		return models.Subscription{}, fmt.Errorf("provider is required")
+		return models.Subscription{}, invalid("provider", "provider is required")
 	}
 
 	reference := strings.TrimSpace(params.Reference)
@@ -4153,6 +6464,17 @@ func (r *postgresRepository) CreateSubscription(params CreateSubscriptionParams)
 
 	externalRef := strings.TrimSpace(params.ExternalReference)
 
+	gifterID := strings.TrimSpace(params.GifterID)
+	if params.IsGift && gifterID == "" {
+		return models.Subscription{}, fmt.Errorf("gifter is required for gifted subscriptions")
+	}
+	var gifterParam any
+	if params.IsGift {
+		gifterParam = gifterID
+	} else {
+		gifterID = ""
+	}
+
 	id, err := generateID()
 	if err != nil {
 		return models.Subscription{}, err
@@ -4162,37 +6484,32 @@ func (r *postgresRepository) CreateSubscription(params CreateSubscriptionParams)
 	expires := started.Add(params.Duration)
 
 	var subscription models.Subscription
-	saveErr := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
-		if err != nil {
-			return fmt.Errorf("begin create subscription tx: %w", err)
-		}
-		defer rollbackTx(ctx, tx)
-
+	saveErr := r.withTx(txSpec{Name: "create subscription", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
 		if err := ensureChannelExists(ctx, tx, params.ChannelID); err != nil {
 			return err
 		}
 		if err := ensureUserExists(ctx, tx, params.UserID); err != nil {
 			return err
 		}
+		if params.IsGift {
+			if err := ensureUserExists(ctx, tx, gifterID); err != nil {
+				return err
+			}
+		}
 
 		var exists bool
 		if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM subscriptions WHERE provider = $1 AND reference = $2)", provider, reference).Scan(&exists); err != nil {
 			return fmt.Errorf("check subscription reference: %w", err)
 		}
 		if exists {
-			return fmt.Errorf("subscription reference %s/%s already exists", provider, reference)
+			return conflict("reference", "subscription reference %s/%s already exists", provider, reference)
 		}
 
-		_, err = tx.Exec(ctx, "INSERT INTO subscriptions (id, channel_id, user_id, tier, provider, reference, amount, currency, started_at, expires_at, auto_renew, status, external_reference) VALUES ($1, $2, $3, $4, $5, $6, $7::numeric / 100000000::numeric, $8, $9, $10, $11, $12, $13)", id, params.ChannelID, params.UserID, tier, provider, reference, amount.MinorUnits(), currency, started, expires, params.AutoRenew, "active", externalRef)
+		_, err = tx.Exec(ctx, "INSERT INTO subscriptions (id, channel_id, user_id, tier, provider, reference, amount, currency, started_at, expires_at, auto_renew, status, external_reference, gifter_id, is_gift) VALUES ($1, $2, $3, $4, $5, $6, $7::numeric / 100000000::numeric, $8, $9, $10, $11, $12, $13, $14, $15)", id, params.ChannelID, params.UserID, tier, provider, reference, amount.MinorUnits(), currency, started, expires, params.AutoRenew, "active", externalRef, gifterParam, params.IsGift)
 		if err != nil {
 			return fmt.Errorf("insert subscription: %w", err)
 		}
 
-		if err := tx.Commit(ctx); err != nil {
-			return fmt.Errorf("commit create subscription: %w", err)
-		}
-
 		subscription = models.Subscription{
 			ID:                id,
 			ChannelID:         params.ChannelID,
@@ -4207,6 +6524,8 @@ func (r *postgresRepository) CreateSubscription(params CreateSubscriptionParams)
 			AutoRenew:         params.AutoRenew,
 			Status:            "active",
 			ExternalReference: externalRef,
+			GifterID:          gifterID,
+			IsGift:            params.IsGift,
 		}
 
 		return nil
@@ -4218,24 +6537,101 @@ func (r *postgresRepository) CreateSubscription(params CreateSubscriptionParams)
 	return subscription, nil
 }
 
-func (r *postgresRepository) ListSubscriptions(channelID string, includeInactive bool) ([]models.Subscription, error) {
+func (r *postgresRepository) GiftSubscriptions(params GiftSubscriptionsParams) ([]models.Subscription, error) {
 	if r == nil || r.pool == nil {
 		return nil, ErrPostgresUnavailable
 	}
 
-	subscriptions := make([]models.Subscription, 0)
-	listErr := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		tx, err := conn.BeginTx(ctx, pgx.TxOptions{AccessMode: pgx.ReadOnly})
-		if err != nil {
-			return fmt.Errorf("begin list subscriptions tx: %w", err)
+	normalized, err := normalizeGiftSubscriptionsParams(params)
+	if err != nil {
+		return nil, err
+	}
+
+	var results []models.Subscription
+	saveErr := r.withTx(txSpec{Name: "gift subscriptions", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		if err := ensureChannelExists(ctx, tx, normalized.ChannelID); err != nil {
+			return err
+		}
+		if err := ensureUserExists(ctx, tx, normalized.GifterID); err != nil {
+			return err
+		}
+
+		now := time.Now().UTC()
+		created := make([]models.Subscription, 0, len(normalized.RecipientIDs))
+		for i, recipient := range normalized.RecipientIDs {
+			if err := ensureUserExists(ctx, tx, recipient); err != nil {
+				return err
+			}
+
+			row := tx.QueryRow(ctx, "SELECT id, channel_id, user_id, tier, provider, reference, (amount * 100000000)::bigint AS amount_minor, currency, started_at, expires_at, auto_renew, status, cancelled_by, cancelled_reason, cancelled_at, external_reference, gifter_id, is_gift FROM subscriptions WHERE channel_id = $1 AND user_id = $2 AND status = 'active' AND expires_at > $3 ORDER BY expires_at DESC LIMIT 1 FOR UPDATE", normalized.ChannelID, recipient, now)
+			existing, err := scanSubscriptionRow(row)
+			if err == nil {
+				existing.ExpiresAt = existing.ExpiresAt.Add(normalized.Duration)
+				if _, err := tx.Exec(ctx, "UPDATE subscriptions SET expires_at = $1 WHERE id = $2", existing.ExpiresAt, existing.ID); err != nil {
+					return fmt.Errorf("extend subscription %s: %w", existing.ID, err)
+				}
+				created = append(created, existing)
+				continue
+			}
+			if !errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Errorf("load active subscription for %s: %w", recipient, err)
+			}
+
+			reference := giftSubscriptionReference(normalized.Reference, i+1)
+			var exists bool
+			if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM subscriptions WHERE provider = $1 AND reference = $2)", normalized.Provider, reference).Scan(&exists); err != nil {
+				return fmt.Errorf("check subscription reference: %w", err)
+			}
+			if exists {
+				return conflict("reference", "subscription reference %s/%s already exists", normalized.Provider, reference)
+			}
+			id, err := generateID()
+			if err != nil {
+				return err
+			}
+			sub := models.Subscription{
+				ID:        id,
+				ChannelID: normalized.ChannelID,
+				UserID:    recipient,
+				Tier:      normalized.Tier,
+				Provider:  normalized.Provider,
+				Reference: reference,
+				Amount:    normalized.Amount,
+				Currency:  normalized.Currency,
+				StartedAt: now,
+				ExpiresAt: now.Add(normalized.Duration),
+				Status:    "active",
+				GifterID:  normalized.GifterID,
+				IsGift:    true,
+			}
+			if _, err := tx.Exec(ctx, "INSERT INTO subscriptions (id, channel_id, user_id, tier, provider, reference, amount, currency, started_at, expires_at, auto_renew, status, gifter_id, is_gift) VALUES ($1, $2, $3, $4, $5, $6, $7::numeric / 100000000::numeric, $8, $9, $10, FALSE, $11, $12, TRUE)", sub.ID, sub.ChannelID, sub.UserID, sub.Tier, sub.Provider, sub.Reference, sub.Amount.MinorUnits(), sub.Currency, sub.StartedAt, sub.ExpiresAt, sub.Status, sub.GifterID); err != nil {
+				return fmt.Errorf("insert gifted subscription: %w", err)
+			}
+			created = append(created, sub)
 		}
-		defer rollbackTx(ctx, tx)
 
+		results = created
+		return nil
+	})
+	if saveErr != nil {
+		return nil, saveErr
+	}
+	return results, nil
+}
+
+func (r *postgresRepository) ListSubscriptions(channelID string, includeInactive bool) ([]models.Subscription, error) {
+	if r == nil || r.pool == nil {
+		return nil, ErrPostgresUnavailable
+	}
+
+	subscriptions := make([]models.Subscription, 0)
+	listErr := r.withTx(txSpec{Name: "list subscriptions", RetrySafe: true, Options: pgx.TxOptions{AccessMode: pgx.ReadOnly}}, func(ctx context.Context, tx pgx.Tx) error {
+		subscriptions = subscriptions[:0]
 		if err := ensureChannelExists(ctx, tx, channelID); err != nil {
 			return err
 		}
 
-		query := "SELECT id, channel_id, user_id, tier, provider, reference, (amount * 100000000)::bigint AS amount_minor, currency, started_at, expires_at, auto_renew, status, cancelled_by, cancelled_reason, cancelled_at, external_reference FROM subscriptions WHERE channel_id = $1"
+		query := "SELECT id, channel_id, user_id, tier, provider, reference, (amount * 100000000)::bigint AS amount_minor, currency, started_at, expires_at, auto_renew, status, cancelled_by, cancelled_reason, cancelled_at, external_reference, gifter_id, is_gift FROM subscriptions WHERE channel_id = $1"
 		args := []any{channelID}
 		if !includeInactive {
 			query += " AND status = 'active'"
@@ -4259,10 +6655,6 @@ func (r *postgresRepository) ListSubscriptions(channelID string, includeInactive
 			return err
 		}
 
-		if err := tx.Commit(ctx); err != nil {
-			return fmt.Errorf("commit list subscriptions: %w", err)
-		}
-
 		return nil
 	})
 	if listErr != nil {
@@ -4278,7 +6670,7 @@ func (r *postgresRepository) GetSubscription(id string) (models.Subscription, bo
 	}
 
 	ctx, cancel := r.acquireContext()
-	row := r.pool.QueryRow(ctx, "SELECT id, channel_id, user_id, tier, provider, reference, (amount * 100000000)::bigint AS amount_minor, currency, started_at, expires_at, auto_renew, status, cancelled_by, cancelled_reason, cancelled_at, external_reference FROM subscriptions WHERE id = $1", id)
+	row := r.pool.QueryRow(ctx, "SELECT id, channel_id, user_id, tier, provider, reference, (amount * 100000000)::bigint AS amount_minor, currency, started_at, expires_at, auto_renew, status, cancelled_by, cancelled_reason, cancelled_at, external_reference, gifter_id, is_gift FROM subscriptions WHERE id = $1", id)
 	cancel()
 
 	sub, err := scanSubscriptionRow(row)
@@ -4300,27 +6692,18 @@ func (r *postgresRepository) CancelSubscription(id, cancelledBy, reason string)
 	trimmedReason := strings.TrimSpace(reason)
 
 	var updated models.Subscription
-	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
-		if err != nil {
-			return fmt.Errorf("begin cancel subscription tx: %w", err)
-		}
-		defer rollbackTx(ctx, tx)
-
-		row := tx.QueryRow(ctx, "SELECT id, channel_id, user_id, tier, provider, reference, (amount * 100000000)::bigint AS amount_minor, currency, started_at, expires_at, auto_renew, status, cancelled_by, cancelled_reason, cancelled_at, external_reference FROM subscriptions WHERE id = $1 FOR UPDATE", id)
+	err := r.withTx(txSpec{Name: "cancel subscription", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, "SELECT id, channel_id, user_id, tier, provider, reference, (amount * 100000000)::bigint AS amount_minor, currency, started_at, expires_at, auto_renew, status, cancelled_by, cancelled_reason, cancelled_at, external_reference, gifter_id, is_gift FROM subscriptions WHERE id = $1 FOR UPDATE", id)
 		sub, err := scanSubscriptionRow(row)
 		if err != nil {
 			if errors.Is(err, pgx.ErrNoRows) {
-				return fmt.Errorf("subscription %s not found", id)
+				return notFound("subscription", id)
 			}
 			return fmt.Errorf("load subscription: %w", err)
 		}
 
 		if strings.EqualFold(sub.Status, "cancelled") {
 			updated = sub
-			if err := tx.Commit(ctx); err != nil {
-				return fmt.Errorf("commit cancel subscription no-op: %w", err)
-			}
 			return nil
 		}
 
@@ -4343,10 +6726,6 @@ func (r *postgresRepository) CancelSubscription(id, cancelledBy, reason string)
 			return fmt.Errorf("update subscription cancellation: %w", err)
 		}
 
-		if err := tx.Commit(ctx); err != nil {
-			return fmt.Errorf("commit cancel subscription: %w", err)
-		}
-
 		sub.Status = "cancelled"
 		sub.AutoRenew = false
 		sub.CancelledBy = cancelledBy
@@ -4363,6 +6742,61 @@ func (r *postgresRepository) CancelSubscription(id, cancelledBy, reason string)
 	return updated, nil
 }
 
+// querySubscriptionStandings summarises the subscriptions of userIDs to the
+// channel at now, keyed by user. It reads through
+// subscriptions_user_channel_idx.
+func querySubscriptionStandings(ctx context.Context, q rowsQuerier, channelID string, userIDs []string, now time.Time) (map[string]SubscriptionStanding, error) {
+	rows, err := q.Query(ctx, "SELECT user_id, status, started_at, expires_at FROM subscriptions WHERE channel_id = $1 AND user_id = ANY($2)", channelID, userIDs)
+	if err != nil {
+		return nil, fmt.Errorf("list subscriptions: %w", err)
+	}
+	defer rows.Close()
+	subs := make(map[string][]models.Subscription)
+	for rows.Next() {
+		var sub models.Subscription
+		if err := rows.Scan(&sub.UserID, &sub.Status, &sub.StartedAt, &sub.ExpiresAt); err != nil {
+			return nil, fmt.Errorf("scan subscription: %w", err)
+		}
+		sub.StartedAt = sub.StartedAt.UTC()
+		sub.ExpiresAt = sub.ExpiresAt.UTC()
+		subs[sub.UserID] = append(subs[sub.UserID], sub)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate subscriptions: %w", err)
+	}
+	standings := make(map[string]SubscriptionStanding, len(userIDs))
+	for _, userID := range userIDs {
+		standings[userID] = subscriptionStanding(channelID, userID, subs[userID], now)
+	}
+	return standings, nil
+}
+
+func (r *postgresRepository) SubscriptionStanding(channelID, userID string) (SubscriptionStanding, error) {
+	if r == nil || r.pool == nil {
+		return SubscriptionStanding{}, ErrPostgresUnavailable
+	}
+	var standing SubscriptionStanding
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		var exists bool
+		if err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM channels WHERE id = $1)", channelID).Scan(&exists); err != nil {
+			return fmt.Errorf("check channel %s: %w", channelID, err)
+		}
+		if !exists {
+			return notFound("channel", channelID)
+		}
+		standings, err := querySubscriptionStandings(ctx, conn, channelID, []string{userID}, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		standing = standings[userID]
+		return nil
+	})
+	if err != nil {
+		return SubscriptionStanding{}, err
+	}
+	return standing, nil
+}
+
 func (r *postgresRepository) AuthenticateOAuth(params OAuthLoginParams) (models.User, error) {
 	if r == nil || r.pool == nil {
 		return models.User{}, ErrPostgresUnavailable
@@ -4371,10 +6805,10 @@ func (r *postgresRepository) AuthenticateOAuth(params OAuthLoginParams) (models.
 	provider := strings.ToLower(strings.TrimSpace(params.Provider))
 	subject := strings.TrimSpace(params.Subject)
 	if provider == "" {
-		return models.User{}, fmt.Errorf("provider is required")
+		return models.User{}, invalid("provider", "provider is required")
 	}
 	if subject == "" {
-		return models.User{}, fmt.Errorf("subject is required")
+		return models.User{}, invalid("subject", "subject is required")
 	}
 
 	normalizedEmail := strings.TrimSpace(strings.ToLower(params.Email))
@@ -4387,24 +6821,24 @@ func (r *postgresRepository) AuthenticateOAuth(params OAuthLoginParams) (models.
 	}
 
 	var user models.User
-	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
-		tx, err := conn.BeginTx(ctx, pgx.TxOptions{})
-		if err != nil {
-			return fmt.Errorf("begin oauth tx: %w", err)
-		}
-		defer rollbackTx(ctx, tx)
-
-		var userID string
-		lookupErr := tx.QueryRow(ctx, "SELECT user_id FROM oauth_accounts WHERE provider = $1 AND subject = $2", provider, subject).Scan(&userID)
+	err := r.withTx(txSpec{Name: "oauth login", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		var (
+			userID        string
+			storedSubject string
+			err           error
+		)
+		// Sealed subjects are found by blind index; rows written before
+		// encryption was enabled still hold the plaintext subject.
+		lookupErr := tx.QueryRow(ctx, "SELECT subject, user_id FROM oauth_accounts WHERE provider = $1 AND (subject_index = ANY($2) OR (subject_index IS NULL AND subject = $3))", provider, oauthSubjectIndexes(r.secrets, provider, subject), subject).Scan(&storedSubject, &userID)
 		if lookupErr != nil && !errors.Is(lookupErr, pgx.ErrNoRows) {
 			return fmt.Errorf("lookup oauth account: %w", lookupErr)
 		}
 		if lookupErr == nil {
-			row := tx.QueryRow(ctx, "SELECT id, display_name, email, roles, password_hash, self_signup, created_at FROM users WHERE id = $1", userID)
+			row := tx.QueryRow(ctx, "SELECT id, display_name, email, roles, password_hash, self_signup, created_at, birth_date, age_confirmed_at, deactivated_at, username, username_changed_at, username_grace, max_concurrent_streams FROM users WHERE id = $1", userID)
 			loaded, err := scanUser(row)
 			if err != nil {
 				if errors.Is(err, pgx.ErrNoRows) {
-					if _, execErr := tx.Exec(ctx, "DELETE FROM oauth_accounts WHERE provider = $1 AND subject = $2", provider, subject); execErr != nil {
+					if _, execErr := tx.Exec(ctx, "DELETE FROM oauth_accounts WHERE provider = $1 AND subject = $2", provider, storedSubject); execErr != nil {
 						return fmt.Errorf("delete stale oauth account: %w", execErr)
 					}
 				} else {
@@ -4412,9 +6846,6 @@ func (r *postgresRepository) AuthenticateOAuth(params OAuthLoginParams) (models.
 				}
 			} else {
 				user = loaded
-				if err := tx.Commit(ctx); err != nil {
-					return fmt.Errorf("commit oauth tx: %w", err)
-				}
 				return nil
 			}
 		}
@@ -4432,21 +6863,27 @@ func (r *postgresRepository) AuthenticateOAuth(params OAuthLoginParams) (models.
 				return err
 			}
 			roles := []string{"viewer"}
+			username, err := deriveUsername(displayName, normalizedEmail, postgresUsernameTaken(ctx, tx, ""))
+			if err != nil {
+				return err
+			}
 			createdAt := now
-			err = tx.QueryRow(ctx, "INSERT INTO users (id, display_name, email, roles, self_signup) VALUES ($1, $2, $3, $4, $5) RETURNING created_at", userID, displayName, normalizedEmail, roles, true).Scan(&createdAt)
+			err = tx.QueryRow(ctx, "INSERT INTO users (id, display_name, email, roles, self_signup, username, username_grace) VALUES ($1, $2, $3, $4, $5, $6, TRUE) RETURNING created_at", userID, displayName, normalizedEmail, roles, true, username).Scan(&createdAt)
 			if err != nil {
 				return fmt.Errorf("create oauth user: %w", err)
 			}
 			user = models.User{
-				ID:          userID,
-				DisplayName: displayName,
-				Email:       normalizedEmail,
-				Roles:       roles,
-				SelfSignup:  true,
-				CreatedAt:   createdAt.UTC(),
+				ID:            userID,
+				DisplayName:   displayName,
+				Username:      username,
+				Email:         normalizedEmail,
+				Roles:         roles,
+				SelfSignup:    true,
+				CreatedAt:     createdAt.UTC(),
+				UsernameGrace: true,
 			}
 		} else {
-			row := tx.QueryRow(ctx, "SELECT id, display_name, email, roles, password_hash, self_signup, created_at FROM users WHERE id = $1 FOR UPDATE", userID)
+			row := tx.QueryRow(ctx, "SELECT id, display_name, email, roles, password_hash, self_signup, created_at, birth_date, age_confirmed_at, deactivated_at, username, username_changed_at, username_grace, max_concurrent_streams FROM users WHERE id = $1 FOR UPDATE", userID)
 			loaded, err := scanUser(row)
 			if err != nil {
 				return fmt.Errorf("load existing user: %w", err)
@@ -4460,22 +6897,36 @@ func (r *postgresRepository) AuthenticateOAuth(params OAuthLoginParams) (models.
 			user = loaded
 		}
 
-		_, err = tx.Exec(ctx, `INSERT INTO oauth_accounts (provider, subject, user_id, email, display_name, linked_at)
+		sealedEmail, err := r.secrets.EncryptString(oauthEmailOwner(provider, subject), normalizedEmail)
+		if err != nil {
+			return fmt.Errorf("encrypt oauth email: %w", err)
+		}
+		sealedSubject, index, err := sealOAuthSubject(r.secrets, provider, subject)
+		if err != nil {
+			return err
+		}
+		if index == "" {
+			_, err = tx.Exec(ctx, `INSERT INTO oauth_accounts (provider, subject, user_id, email, display_name, linked_at)
 VALUES ($1, $2, $3, $4, $5, NOW())
 ON CONFLICT (provider, subject) DO UPDATE
-SET user_id = EXCLUDED.user_id, email = EXCLUDED.email, display_name = EXCLUDED.display_name, linked_at = NOW()`, provider, subject, user.ID, normalizedEmail, displayName)
+SET user_id = EXCLUDED.user_id, email = EXCLUDED.email, display_name = EXCLUDED.display_name, linked_at = NOW()`, provider, subject, user.ID, sealedEmail, displayName)
+		} else {
+			_, err = tx.Exec(ctx, `INSERT INTO oauth_accounts (provider, subject, subject_index, user_id, email, display_name, linked_at)
+VALUES ($1, $2, $3, $4, $5, $6, NOW())
+ON CONFLICT (provider, subject_index) WHERE subject_index IS NOT NULL DO UPDATE
+SET user_id = EXCLUDED.user_id, email = EXCLUDED.email, display_name = EXCLUDED.display_name, linked_at = NOW()`, provider, sealedSubject, index, user.ID, sealedEmail, displayName)
+		}
 		if err != nil {
 			return fmt.Errorf("upsert oauth account: %w", err)
 		}
-
-		if err := tx.Commit(ctx); err != nil {
-			return fmt.Errorf("commit oauth tx: %w", err)
-		}
 		return nil
 	})
 	if err != nil {
 		return models.User{}, err
 	}
+	if user.Deactivated() {
+		return models.User{}, ErrUserDeactivated
+	}
 	return user, nil
 }
 
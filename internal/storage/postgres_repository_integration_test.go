@@ -21,6 +21,7 @@ import (
 	"bitriver-live/internal/ingest"
 	"bitriver-live/internal/models"
 	"bitriver-live/internal/storage"
+	"bitriver-live/internal/storage/storagetest"
 	"github.com/jackc/pgx/v5"
 	"github.com/jackc/pgx/v5/pgtype"
 	"github.com/jackc/pgx/v5/pgxpool"
@@ -170,6 +171,24 @@ func TestPostgresRepositoryAcquireTimeoutCoversQueries(t *testing.T) {
 	}
 }
 
+func TestPostgresRepositoryConformance(t *testing.T) {
+	// Open once up front so a stubbed driver or missing DSN skips or fails
+	// the whole suite here rather than inside each case.
+	if _, _, err := postgresRepositoryFactory(t); errors.Is(err, storage.ErrPostgresUnavailable) {
+		t.Skip("postgres repository unavailable")
+	}
+	storagetest.RunRepositoryTests(t, func() storage.Repository {
+		repo, _, err := postgresRepositoryFactory(t, storage.WithSecretKey(storagetest.SecretKey))
+		if errors.Is(err, storage.ErrPostgresUnavailable) {
+			return nil
+		}
+		if err != nil {
+			t.Fatalf("open postgres repository: %v", err)
+		}
+		return repo
+	})
+}
+
 func TestPostgresUserLifecycle(t *testing.T) {
 	storage.RunRepositoryUserLifecycle(t, postgresRepositoryFactory)
 }
@@ -182,6 +201,10 @@ func TestPostgresChatReportsLifecycle(t *testing.T) {
 	storage.RunRepositoryChatReportsLifecycle(t, postgresRepositoryFactory)
 }
 
+func TestPostgresChatMessagesInRange(t *testing.T) {
+	storage.RunRepositoryChatMessagesInRange(t, postgresRepositoryFactory)
+}
+
 func TestPostgresChannelSearch(t *testing.T) {
 	storage.RunRepositoryChannelSearch(t, postgresRepositoryFactory)
 }
@@ -228,7 +251,7 @@ func TestPostgresChatMessageLifecycle(t *testing.T) {
 		t.Fatalf("create channel: %v", err)
 	}
 
-	message, err := repo.CreateChatMessage(channel.ID, owner.ID, "hello world")
+	message, err := repo.CreateChatMessage(channel.ID, owner.ID, "hello world", "")
 	if err != nil {
 		t.Fatalf("create chat message: %v", err)
 	}
@@ -244,7 +267,7 @@ func TestPostgresChatMessageLifecycle(t *testing.T) {
 		t.Fatalf("expected stored message in history, got %+v", history)
 	}
 
-	if err := repo.DeleteChatMessage(channel.ID, message.ID); err != nil {
+	if err := repo.DeleteChatMessage(channel.ID, message.ID, owner.ID); err != nil {
 		t.Fatalf("delete chat message: %v", err)
 	}
 	history, err = repo.ListChatMessages(channel.ID, 0)
@@ -301,15 +324,15 @@ func TestPostgresChatMessageHistoryPaging(t *testing.T) {
 		t.Fatalf("create channel: %v", err)
 	}
 
-	msg1, err := repo.CreateChatMessage(channel.ID, owner.ID, "first")
+	msg1, err := repo.CreateChatMessage(channel.ID, owner.ID, "first", "")
 	if err != nil {
 		t.Fatalf("create chat message #1: %v", err)
 	}
-	msg2, err := repo.CreateChatMessage(channel.ID, owner.ID, "second")
+	msg2, err := repo.CreateChatMessage(channel.ID, owner.ID, "second", "")
 	if err != nil {
 		t.Fatalf("create chat message #2: %v", err)
 	}
-	msg3, err := repo.CreateChatMessage(channel.ID, owner.ID, "third")
+	msg3, err := repo.CreateChatMessage(channel.ID, owner.ID, "third", "")
 	if err != nil {
 		t.Fatalf("create chat message #3: %v", err)
 	}
@@ -688,7 +711,7 @@ func TestPostgresReadHelpersRespectAcquireTimeout(t *testing.T) {
 	if err != nil {
 		t.Fatalf("create channel: %v", err)
 	}
-	msg, err := repo.CreateChatMessage(channel.ID, owner.ID, "ping")
+	msg, err := repo.CreateChatMessage(channel.ID, owner.ID, "ping", "")
 	if err != nil {
 		t.Fatalf("create chat message: %v", err)
 	}
@@ -736,8 +759,9 @@ func TestPostgresReadHelpersRespectAcquireTimeout(t *testing.T) {
 	}
 
 	expectQuick("ListChannels", func() error {
-		if channels := repo.ListChannels("", ""); channels != nil {
-			return fmt.Errorf("expected nil channel list while pool is exhausted, got %d", len(channels))
+		channels, err := repo.ListChannels("", "")
+		if err == nil {
+			return fmt.Errorf("expected an error while pool is exhausted, got %d channels", len(channels))
 		}
 		return nil
 	})
@@ -771,7 +795,10 @@ func TestPostgresReadHelpersRespectAcquireTimeout(t *testing.T) {
 	conn.Release()
 	conn = nil
 
-	channels := repo.ListChannels("", "")
+	channels, err := repo.ListChannels("", "")
+	if err != nil {
+		t.Fatalf("ListChannels: %v", err)
+	}
 	if len(channels) != 1 || channels[0].ID != channel.ID {
 		t.Fatalf("expected channel to be listed after releasing pool connection, got %+v", channels)
 	}
@@ -827,7 +854,7 @@ func TestPostgresChatBanTimeoutLifecycle(t *testing.T) {
 		t.Fatalf("apply timeout event: %v", err)
 	}
 
-	if _, err := repo.CreateChatMessage(channel.ID, target.ID, "should fail"); err == nil || !strings.Contains(err.Error(), "timed out") {
+	if _, err := repo.CreateChatMessage(channel.ID, target.ID, "should fail", ""); err == nil || !strings.Contains(err.Error(), "timed out") {
 		t.Fatalf("expected timeout error, got %v", err)
 	}
 
@@ -846,7 +873,7 @@ func TestPostgresChatBanTimeoutLifecycle(t *testing.T) {
 		t.Fatalf("apply ban event: %v", err)
 	}
 
-	if _, err := repo.CreateChatMessage(channel.ID, target.ID, "still banned"); err == nil || !strings.Contains(err.Error(), "banned") {
+	if _, err := repo.CreateChatMessage(channel.ID, target.ID, "still banned", ""); err == nil || !strings.Contains(err.Error(), "banned") {
 		t.Fatalf("expected ban error, got %v", err)
 	}
 
@@ -857,7 +884,10 @@ func TestPostgresChatBanTimeoutLifecycle(t *testing.T) {
 		t.Fatalf("expected timeout expiry to be recorded")
 	}
 
-	restrictions := repo.ListChatRestrictions(channel.ID)
+	restrictions, err := repo.ListChatRestrictions(channel.ID)
+	if err != nil {
+		t.Fatalf("ListChatRestrictions: %v", err)
+	}
 	if len(restrictions) != 2 {
 		t.Fatalf("expected ban and timeout restrictions, got %v", restrictions)
 	}
@@ -921,7 +951,11 @@ func TestPostgresChatBanTimeoutLifecycle(t *testing.T) {
 	if _, ok := repo.ChatTimeout(channel.ID, target.ID); ok {
 		t.Fatalf("expected timeout to be cleared")
 	}
-	if remaining := repo.ListChatRestrictions(channel.ID); len(remaining) != 0 {
+	remaining, err := repo.ListChatRestrictions(channel.ID)
+	if err != nil {
+		t.Fatalf("ListChatRestrictions: %v", err)
+	}
+	if len(remaining) != 0 {
 		t.Fatalf("expected no remaining restrictions, got %v", remaining)
 	}
 }
@@ -980,7 +1014,10 @@ func TestPostgresListChatRestrictionsSkipsExpiredTimeouts(t *testing.T) {
 		t.Fatalf("apply expired timeout: %v", err)
 	}
 
-	restrictions := repo.ListChatRestrictions(channel.ID)
+	restrictions, err := repo.ListChatRestrictions(channel.ID)
+	if err != nil {
+		t.Fatalf("ListChatRestrictions: %v", err)
+	}
 	if len(restrictions) != 1 {
 		t.Fatalf("expected 1 restriction, got %v", restrictions)
 	}
@@ -1091,6 +1128,42 @@ func TestPostgresChatReportAllowsMissingMessage(t *testing.T) {
 	}
 }
 
+func TestPostgresChatReportsDefaultToMessageSubjects(t *testing.T) {
+	repo, cleanup, err := postgresRepositoryFactory(t)
+	if errors.Is(err, storage.ErrPostgresUnavailable) {
+		t.Skip("postgres repository unavailable in this build")
+	}
+	if err != nil {
+		t.Fatalf("failed to open postgres repository: %v", err)
+	}
+	if cleanup != nil {
+		defer cleanup()
+	}
+
+	pool := postgresPoolFromRepository(t, repo)
+	ctx := context.Background()
+	now := time.Now().UTC()
+	if _, err := pool.Exec(ctx, "INSERT INTO users (id, display_name, email, roles, created_at) VALUES ($1, $2, $3, $4, $5), ($6, $7, $8, $9, $10)", "user-legacy-owner", "Owner", "legacy-owner@example.com", []string{"creator"}, now, "user-legacy-viewer", "Viewer", "legacy-viewer@example.com", []string{}, now); err != nil {
+		t.Fatalf("insert users: %v", err)
+	}
+	if _, err := pool.Exec(ctx, "INSERT INTO channels (id, owner_id, stream_key, title, live_state) VALUES ($1, $2, $3, $4, $5)", "channel-legacy-reports", "user-legacy-owner", "legacy-report-key", "Legacy", "offline"); err != nil {
+		t.Fatalf("insert channel: %v", err)
+	}
+	// A row written by a server that predates report subjects names none
+	// of the new columns.
+	if _, err := pool.Exec(ctx, "INSERT INTO chat_reports (id, channel_id, reporter_id, target_id, reason, status, created_at) VALUES ($1, $2, $3, $4, $5, $6, $7)", "report-legacy", "channel-legacy-reports", "user-legacy-viewer", "user-legacy-owner", "spam", "open", now); err != nil {
+		t.Fatalf("insert legacy report: %v", err)
+	}
+
+	reports, err := repo.ListReports(storage.ReportFilter{SubjectType: models.ReportSubjectChatMessage, Category: models.ReportCategoryOther})
+	if err != nil {
+		t.Fatalf("list reports: %v", err)
+	}
+	if len(reports) != 1 || reports[0].ID != "report-legacy" || reports[0].Claimant != nil {
+		t.Fatalf("expected the legacy row as a chat message report in the other category, got %+v", reports)
+	}
+}
+
 func TestPostgresTipsLifecycle(t *testing.T) {
 	storage.RunRepositoryTipsLifecycle(t, postgresRepositoryFactory)
 }
@@ -1140,6 +1213,10 @@ func TestPostgresSubscriptionsLifecycle(t *testing.T) {
 	storage.RunRepositorySubscriptionsLifecycle(t, postgresRepositoryFactory)
 }
 
+func TestPostgresSubscriptionGifts(t *testing.T) {
+	storage.RunRepositorySubscriptionGifts(t, postgresRepositoryFactory)
+}
+
 func TestPostgresMonetizationPrecision(t *testing.T) {
 	storage.RunRepositoryMonetizationPrecision(t, postgresRepositoryFactory)
 }
@@ -1255,14 +1332,70 @@ func TestPostgresSubscriptionCancellationMetadata(t *testing.T) {
 	}
 }
 
+func TestPostgresAPITokensLifecycle(t *testing.T) {
+	storage.RunRepositoryAPITokensLifecycle(t, postgresRepositoryFactory)
+}
+
+func TestPostgresNotificationPreferences(t *testing.T) {
+	storage.RunRepositoryNotificationPreferences(t, postgresRepositoryFactory)
+}
+
 func TestPostgresStreamKeyRotation(t *testing.T) {
 	storage.RunRepositoryStreamKeyRotation(t, postgresRepositoryFactory)
 }
 
+func TestPostgresUserDeactivation(t *testing.T) {
+	storage.RunRepositoryUserDeactivation(t, postgresRepositoryFactory)
+}
+
+func TestPostgresUserListing(t *testing.T) {
+	storage.RunRepositoryUserListing(t, postgresRepositoryFactory)
+}
+
 func TestPostgresChannelLookupByStreamKey(t *testing.T) {
 	storage.RunRepositoryChannelLookupByStreamKey(t, postgresRepositoryFactory)
 }
 
+func TestPostgresChannelPlaybackRestriction(t *testing.T) {
+	storage.RunRepositoryChannelPlaybackRestriction(t, postgresRepositoryFactory)
+}
+
+func TestPostgresChannelTranscodeLimits(t *testing.T) {
+	storage.RunRepositoryChannelTranscodeLimits(t, postgresRepositoryFactory)
+}
+
+func TestPostgresLiveClips(t *testing.T) {
+	storage.RunRepositoryLiveClips(t, postgresRepositoryFactory)
+}
+
+func TestPostgresStreamMarkers(t *testing.T) {
+	storage.RunRepositoryStreamMarkers(t, postgresRepositoryFactory)
+}
+
+func TestPostgresMatureContentAndAgeConfirmation(t *testing.T) {
+	storage.RunRepositoryMatureContentAndAgeConfirmation(t, postgresRepositoryFactory)
+}
+
+func TestPostgresChannelBatchUpdate(t *testing.T) {
+	storage.RunRepositoryChannelBatchUpdate(t, postgresRepositoryFactory)
+}
+
+func TestPostgresChannelFollowers(t *testing.T) {
+	storage.RunRepositoryChannelFollowers(t, postgresRepositoryFactory)
+}
+
+func TestPostgresChannelEditorGrants(t *testing.T) {
+	storage.RunRepositoryChannelEditorGrants(t, postgresRepositoryFactory)
+}
+
+func TestPostgresChannelModeratorGrants(t *testing.T) {
+	storage.RunRepositoryChannelModeratorGrants(t, postgresRepositoryFactory)
+}
+
+func TestPostgresJobQueue(t *testing.T) {
+	storage.RunRepositoryJobQueue(t, postgresRepositoryFactory)
+}
+
 func TestPostgresIngestHealthSnapshots(t *testing.T) {
 	storage.RunRepositoryIngestHealthSnapshots(t, postgresRepositoryFactory)
 }
@@ -1271,6 +1404,10 @@ func TestPostgresRecordingRetention(t *testing.T) {
 	storage.RunRepositoryRecordingRetention(t, postgresRepositoryFactory)
 }
 
+func TestPostgresRecordingPolicy(t *testing.T) {
+	storage.RunRepositoryRecordingPolicy(t, postgresRepositoryFactory)
+}
+
 func TestPostgresRecordingRetentionFailures(t *testing.T) {
 	storage.RunRepositoryRecordingRetentionFailures(t, postgresRepositoryFactory)
 }
@@ -1287,6 +1424,14 @@ func TestPostgresStreamTimeouts(t *testing.T) {
 	storage.RunRepositoryStreamTimeouts(t, postgresRepositoryFactory)
 }
 
+func TestPostgresStreamSettingsSnapshot(t *testing.T) {
+	storage.RunRepositoryStreamSettingsSnapshot(t, postgresRepositoryFactory)
+}
+
+func TestPostgresStreamPlaybackEndpoints(t *testing.T) {
+	storage.RunRepositoryStreamPlaybackEndpoints(t, postgresRepositoryFactory)
+}
+
 func applyPostgresMigrations(t *testing.T, ctx context.Context, pool *pgxpool.Pool) {
 	t.Helper()
 	_, filename, _, ok := runtime.Caller(0)
@@ -1322,6 +1467,220 @@ func applyPostgresMigrations(t *testing.T, ctx context.Context, pool *pgxpool.Po
 	}
 }
 
+func seedSnapshotExportRepository(t *testing.T, repo storage.Repository) (models.Channel, models.User) {
+	t.Helper()
+	owner, err := repo.CreateUser(storage.CreateUserParams{DisplayName: "owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("create owner: %v", err)
+	}
+	viewer, err := repo.CreateUser(storage.CreateUserParams{DisplayName: "viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("create viewer: %v", err)
+	}
+	if _, err := repo.UpsertProfile(owner.ID, storage.ProfileUpdate{}); err != nil {
+		t.Fatalf("upsert profile: %v", err)
+	}
+	channel, err := repo.CreateChannel(owner.ID, "Lobby", "gaming", []string{"speedrun"})
+	if err != nil {
+		t.Fatalf("create channel: %v", err)
+	}
+	if err := repo.FollowChannel(viewer.ID, channel.ID); err != nil {
+		t.Fatalf("follow channel: %v", err)
+	}
+	if _, _, err := repo.CreateAPIToken(storage.CreateAPITokenParams{UserID: owner.ID, Name: "ci"}); err != nil {
+		t.Fatalf("create api token: %v", err)
+	}
+	if _, err := repo.AuthenticateOAuth(storage.OAuthLoginParams{Provider: "example", Subject: "snapshot", Email: "oauth@example.com", DisplayName: "OAuth"}); err != nil {
+		t.Fatalf("oauth login: %v", err)
+	}
+	if _, err := repo.CreateTip(storage.CreateTipParams{ChannelID: channel.ID, FromUserID: viewer.ID, Amount: models.MustParseMoney("1.25"), Currency: "USD", Provider: "stripe", Reference: "tip-1"}); err != nil {
+		t.Fatalf("create tip: %v", err)
+	}
+	if err := repo.ApplyChatEvent(chat.Event{Type: chat.EventTypeModeration, Moderation: &chat.ModerationEvent{Action: chat.ModerationActionBan, ChannelID: channel.ID, ActorID: owner.ID, TargetID: viewer.ID, Reason: "spam"}, OccurredAt: time.Now().UTC()}); err != nil {
+		t.Fatalf("apply ban: %v", err)
+	}
+	for i := 0; i < 7; i++ {
+		if _, err := repo.CreateChatMessage(channel.ID, owner.ID, fmt.Sprintf("message %d", i), ""); err != nil {
+			t.Fatalf("create chat message %d: %v", i, err)
+		}
+	}
+	return channel, owner
+}
+
+func TestPostgresSnapshotExportRoundTrip(t *testing.T) {
+	repo := openPostgresRepository(t)
+	ctx := context.Background()
+	channel, owner := seedSnapshotExportRepository(t, repo)
+	guest, err := repo.CreateChannel(owner.ID, "Guest", "gaming", nil)
+	if err != nil {
+		t.Fatalf("create guest channel: %v", err)
+	}
+	renamed, err := repo.ChangeChannelSlug(channel.ID, "lobby-two", time.Now().UTC())
+	if err != nil {
+		t.Fatalf("change channel slug: %v", err)
+	}
+	if _, err := repo.StartStream(channel.ID, []string{"720p"}); err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	invite, err := repo.CreateCoStreamInvite(channel.ID, guest.ID, owner.ID)
+	if err != nil {
+		t.Fatalf("create co-stream invite: %v", err)
+	}
+	flag, err := repo.CreateFeatureFlag(storage.FeatureFlagParams{Key: "new-player", Enabled: true, RolloutPercent: 25, AllowUserIDs: []string{owner.ID}})
+	if err != nil {
+		t.Fatalf("create feature flag: %v", err)
+	}
+
+	snapshot, err := storage.ExportSnapshotFromPostgres(ctx, repo)
+	if err != nil {
+		t.Fatalf("export snapshot: %v", err)
+	}
+	counts := snapshot.Counts()
+	if err := storage.VerifySnapshotCounts(ctx, repo, counts); err != nil {
+		t.Fatalf("verify exported counts: %v", err)
+	}
+	if counts.Users != 3 || counts.ChatMessages != 7 || counts.ChatBans != 1 || counts.Follows != 1 || counts.APITokens != 1 || counts.OAuthAccounts != 1 || counts.CoStreamInvites != 1 || counts.FeatureFlags != 1 || counts.ChannelSlugRedirects != 1 {
+		t.Fatalf("unexpected export counts: %+v", counts)
+	}
+	if got := snapshot.Channels[channel.ID].StreamKey; got != channel.StreamKey {
+		t.Fatalf("expected stream key %q to survive export, got %q", channel.StreamKey, got)
+	}
+
+	path := filepath.Join(t.TempDir(), "snapshot.json.gz")
+	if err := storage.SaveSnapshotToJSON(path, snapshot, true); err != nil {
+		t.Fatalf("save snapshot: %v", err)
+	}
+	loaded, err := storage.LoadSnapshotFromJSON(path)
+	if err != nil {
+		t.Fatalf("load snapshot: %v", err)
+	}
+
+	pool := postgresPoolFromRepository(t, repo)
+	if err := truncatePostgresTables(ctx, pool); err != nil {
+		t.Fatalf("truncate tables: %v", err)
+	}
+	if _, err := storage.ImportSnapshotToPostgres(ctx, repo, loaded); err != nil {
+		t.Fatalf("import snapshot: %v", err)
+	}
+	if err := storage.VerifySnapshotCounts(ctx, repo, counts); err != nil {
+		t.Fatalf("verify imported counts: %v", err)
+	}
+	reloaded, ok := repo.GetChannel(channel.ID)
+	if !ok {
+		t.Fatalf("expected channel %s after import", channel.ID)
+	}
+	if !reflect.DeepEqual(reloaded.Tags, channel.Tags) {
+		t.Fatalf("expected tags %v after import, got %v", channel.Tags, reloaded.Tags)
+	}
+	if got, ok := repo.GetCoStreamInvite(invite.ID); !ok || got.GuestChannelID != guest.ID || got.Status != invite.Status {
+		t.Fatalf("expected co-stream invite %+v after import, got %+v", invite, got)
+	}
+	if match, ok := repo.FindChannelBySlug(channel.Slug); !ok || !match.Redirected || match.Channel.Slug != renamed.Slug {
+		t.Fatalf("expected old slug %s to redirect to %s after import, got %+v", channel.Slug, renamed.Slug, match)
+	}
+	if got, ok := repo.GetFeatureFlag(flag.Key); !ok || got.RolloutPercent != flag.RolloutPercent || !reflect.DeepEqual(got.AllowUserIDs, flag.AllowUserIDs) {
+		t.Fatalf("expected feature flag %+v after import, got %+v", flag, got)
+	}
+}
+
+func TestPostgresSnapshotImportResumesAfterRejectedRows(t *testing.T) {
+	repo := openPostgresRepository(t)
+	ctx := context.Background()
+	channel, owner := seedSnapshotExportRepository(t, repo)
+
+	snapshot, err := storage.ExportSnapshotFromPostgres(ctx, repo)
+	if err != nil {
+		t.Fatalf("export snapshot: %v", err)
+	}
+	counts := snapshot.Counts()
+	snapshot.Follows[owner.ID] = map[string]time.Time{"missing-channel": time.Now().UTC()}
+	if violations := storage.ValidateSnapshot(snapshot); len(violations) != 1 || violations[0].Table != "follows" {
+		t.Fatalf("expected one follows violation, got %+v", violations)
+	}
+
+	pool := postgresPoolFromRepository(t, repo)
+	if err := truncatePostgresTables(ctx, pool); err != nil {
+		t.Fatalf("truncate tables: %v", err)
+	}
+	if _, err := storage.ImportSnapshotToPostgres(ctx, repo, snapshot); err == nil {
+		t.Fatal("expected the dangling follow to stop the import")
+	}
+
+	before, err := storage.CountSnapshotTables(ctx, repo)
+	if err != nil {
+		t.Fatalf("count tables: %v", err)
+	}
+	report, err := storage.ImportSnapshotToPostgres(ctx, repo, snapshot, storage.WithSnapshotContinueOnError())
+	if err != nil {
+		t.Fatalf("import with continue-on-error: %v", err)
+	}
+	statuses := make(map[string]string, len(report.Steps))
+	for _, step := range report.Steps {
+		statuses[step.Step] = step.Status
+	}
+	if statuses["users"] != storage.SnapshotStepResumed || statuses["channels"] != storage.SnapshotStepResumed {
+		t.Fatalf("expected the steps before follows to resume, got %v", statuses)
+	}
+	if statuses["follows"] != storage.SnapshotStepIncomplete || statuses["chat_messages"] != storage.SnapshotStepImported {
+		t.Fatalf("expected follows incomplete and later steps imported, got %v", statuses)
+	}
+	if len(report.Failures) != 1 || report.Failures[0].Table != "follows" || report.Failures[0].Key != owner.ID+"/missing-channel" {
+		t.Fatalf("expected the dangling follow in the failures, got %+v", report.Failures)
+	}
+	if err := storage.VerifySnapshotImport(ctx, repo, before, report); err != nil {
+		t.Fatalf("verify partial import: %v", err)
+	}
+
+	delete(snapshot.Follows, owner.ID)
+	before, err = storage.CountSnapshotTables(ctx, repo)
+	if err != nil {
+		t.Fatalf("count tables: %v", err)
+	}
+	report, err = storage.ImportSnapshotToPostgres(ctx, repo, snapshot)
+	if err != nil {
+		t.Fatalf("import fixed snapshot: %v", err)
+	}
+	if len(report.Failures) != 0 {
+		t.Fatalf("expected no failures after the fix, got %+v", report.Failures)
+	}
+	if err := storage.VerifySnapshotImport(ctx, repo, before, report); err != nil {
+		t.Fatalf("verify fixed import: %v", err)
+	}
+	if err := storage.VerifySnapshotCounts(ctx, repo, counts); err != nil {
+		t.Fatalf("verify imported counts: %v", err)
+	}
+	if _, ok := repo.GetChannel(channel.ID); !ok {
+		t.Fatalf("expected channel %s after import", channel.ID)
+	}
+}
+
+func TestPostgresSnapshotExportChunksChatMessages(t *testing.T) {
+	repo := openPostgresRepository(t)
+	ctx := context.Background()
+	channel, _ := seedSnapshotExportRepository(t, repo)
+
+	snapshot, err := storage.ExportSnapshotFromPostgres(ctx, repo, storage.WithSnapshotChatBatchSize(2))
+	if err != nil {
+		t.Fatalf("export snapshot: %v", err)
+	}
+	history, err := repo.ListChatMessages(channel.ID, 0)
+	if err != nil {
+		t.Fatalf("list chat messages: %v", err)
+	}
+	if len(snapshot.ChatMessages) != len(history) {
+		t.Fatalf("expected %d chat messages across batches, got %d", len(history), len(snapshot.ChatMessages))
+	}
+	for _, msg := range history {
+		exported, ok := snapshot.ChatMessages[msg.ID]
+		if !ok {
+			t.Fatalf("chat message %s missing from export", msg.ID)
+		}
+		if exported.Content != msg.Content {
+			t.Fatalf("expected content %q, got %q", msg.Content, exported.Content)
+		}
+	}
+}
+
 func truncatePostgresTables(ctx context.Context, pool *pgxpool.Pool) error {
 	tables, err := storage.PostgresTablesForTest(ctx, pool)
 	if err != nil {
@@ -0,0 +1,269 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"bitriver-live/internal/models"
+)
+
+const (
+	scheduleEntryColumns     = "id, channel_id, title, category, starts_at, duration_minutes, time_zone, recurrence, repeat_until, sequence, created_at, updated_at"
+	scheduleFeedTokenColumns = "user_id, nonce, created_at"
+)
+
+func scanScheduleEntry(row pgx.Row) (models.ScheduleEntry, error) {
+	var (
+		entry       models.ScheduleEntry
+		repeatUntil pgtype.Timestamptz
+	)
+	if err := row.Scan(&entry.ID, &entry.ChannelID, &entry.Title, &entry.Category, &entry.StartsAt, &entry.DurationMinutes, &entry.TimeZone, &entry.Recurrence, &repeatUntil, &entry.Sequence, &entry.CreatedAt, &entry.UpdatedAt); err != nil {
+		return models.ScheduleEntry{}, err
+	}
+	entry.StartsAt = entry.StartsAt.UTC()
+	entry.CreatedAt = entry.CreatedAt.UTC()
+	entry.UpdatedAt = entry.UpdatedAt.UTC()
+	if repeatUntil.Valid {
+		until := repeatUntil.Time.UTC()
+		entry.RepeatUntil = &until
+	}
+	return entry, nil
+}
+
+func scanScheduleFeedToken(row pgx.Row) (models.ScheduleFeedToken, error) {
+	var token models.ScheduleFeedToken
+	if err := row.Scan(&token.UserID, &token.Nonce, &token.CreatedAt); err != nil {
+		return models.ScheduleFeedToken{}, err
+	}
+	token.CreatedAt = token.CreatedAt.UTC()
+	return token, nil
+}
+
+// queryScheduleEntries runs query, which must select scheduleEntryColumns,
+// and collects the entries.
+func queryScheduleEntries(ctx context.Context, q rowsQuerier, query string, args ...any) ([]models.ScheduleEntry, error) {
+	rows, err := q.Query(ctx, query, args...)
+	if err != nil {
+		return nil, fmt.Errorf("list schedule entries: %w", err)
+	}
+	defer rows.Close()
+	entries := make([]models.ScheduleEntry, 0)
+	for rows.Next() {
+		entry, err := scanScheduleEntry(rows)
+		if err != nil {
+			return nil, fmt.Errorf("scan schedule entry: %w", err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate schedule entries: %w", err)
+	}
+	return entries, nil
+}
+
+// CreateScheduleEntry adds an entry to the channel's schedule. The channel
+// row is locked while its entries are counted so concurrent requests cannot
+// overshoot MaxScheduleEntriesPerChannel.
+func (r *postgresRepository) CreateScheduleEntry(channelID string, params ScheduleEntryParams) (models.ScheduleEntry, error) {
+	if r == nil || r.pool == nil {
+		return models.ScheduleEntry{}, ErrPostgresUnavailable
+	}
+	var entry models.ScheduleEntry
+	err := r.withTx(txSpec{Name: "schedule entry", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		var id string
+		if err := tx.QueryRow(ctx, "SELECT id FROM channels WHERE id = $1 FOR UPDATE", channelID).Scan(&id); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return notFound("channel", channelID)
+			}
+			return fmt.Errorf("lock channel %s: %w", channelID, err)
+		}
+		var count int
+		if err := tx.QueryRow(ctx, "SELECT COUNT(*) FROM schedule_entries WHERE channel_id = $1", channelID).Scan(&count); err != nil {
+			return fmt.Errorf("count schedule entries: %w", err)
+		}
+		if count >= MaxScheduleEntriesPerChannel {
+			return ErrScheduleEntryLimit
+		}
+		created, err := newScheduleEntry(channelID, params, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, "INSERT INTO schedule_entries ("+scheduleEntryColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10, $11, $12)",
+			created.ID,
+			created.ChannelID,
+			created.Title,
+			created.Category,
+			created.StartsAt,
+			created.DurationMinutes,
+			created.TimeZone,
+			created.Recurrence,
+			created.RepeatUntil,
+			created.Sequence,
+			created.CreatedAt,
+			created.UpdatedAt,
+		); err != nil {
+			return fmt.Errorf("insert schedule entry: %w", err)
+		}
+		entry = created
+		return nil
+	})
+	if err != nil {
+		return models.ScheduleEntry{}, err
+	}
+	return entry, nil
+}
+
+// ListScheduleEntries returns the channel's schedule by first occurrence.
+func (r *postgresRepository) ListScheduleEntries(channelID string) ([]models.ScheduleEntry, error) {
+	if r == nil || r.pool == nil {
+		return nil, ErrPostgresUnavailable
+	}
+	var entries []models.ScheduleEntry
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		var err error
+		entries, err = queryScheduleEntries(ctx, conn, "SELECT "+scheduleEntryColumns+" FROM schedule_entries WHERE channel_id = $1 ORDER BY starts_at, id", channelID)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// UpdateScheduleEntry changes one of the channel's schedule entries.
+func (r *postgresRepository) UpdateScheduleEntry(channelID, entryID string, update ScheduleEntryUpdate) (models.ScheduleEntry, error) {
+	if r == nil || r.pool == nil {
+		return models.ScheduleEntry{}, ErrPostgresUnavailable
+	}
+	var entry models.ScheduleEntry
+	err := r.withTx(txSpec{Name: "schedule entry", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		current, err := scanScheduleEntry(tx.QueryRow(ctx, "SELECT "+scheduleEntryColumns+" FROM schedule_entries WHERE id = $1 AND channel_id = $2 FOR UPDATE", entryID, channelID))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return ErrScheduleEntryNotFound
+			}
+			return fmt.Errorf("load schedule entry %s: %w", entryID, err)
+		}
+		updated, err := applyScheduleEntryUpdate(current, update, time.Now().UTC())
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, "UPDATE schedule_entries SET title = $1, category = $2, starts_at = $3, duration_minutes = $4, time_zone = $5, recurrence = $6, repeat_until = $7, sequence = $8, updated_at = $9 WHERE id = $10",
+			updated.Title,
+			updated.Category,
+			updated.StartsAt,
+			updated.DurationMinutes,
+			updated.TimeZone,
+			updated.Recurrence,
+			updated.RepeatUntil,
+			updated.Sequence,
+			updated.UpdatedAt,
+			updated.ID,
+		); err != nil {
+			return fmt.Errorf("update schedule entry %s: %w", entryID, err)
+		}
+		entry = updated
+		return nil
+	})
+	if err != nil {
+		return models.ScheduleEntry{}, err
+	}
+	return entry, nil
+}
+
+// DeleteScheduleEntry removes one of the channel's schedule entries.
+func (r *postgresRepository) DeleteScheduleEntry(channelID, entryID string) error {
+	if r == nil || r.pool == nil {
+		return ErrPostgresUnavailable
+	}
+	return r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		tag, err := conn.Exec(ctx, "DELETE FROM schedule_entries WHERE id = $1 AND channel_id = $2", entryID, channelID)
+		if err != nil {
+			return fmt.Errorf("delete schedule entry %s: %w", entryID, err)
+		}
+		if tag.RowsAffected() == 0 {
+			return ErrScheduleEntryNotFound
+		}
+		return nil
+	})
+}
+
+// IssueScheduleFeedToken gives the user a new followed-schedule feed token,
+// replacing any previous one so its URL stops working.
+func (r *postgresRepository) IssueScheduleFeedToken(userID string) (models.ScheduleFeedToken, error) {
+	if r == nil || r.pool == nil {
+		return models.ScheduleFeedToken{}, ErrPostgresUnavailable
+	}
+	nonce, err := generateID()
+	if err != nil {
+		return models.ScheduleFeedToken{}, err
+	}
+	token := models.ScheduleFeedToken{UserID: userID, Nonce: nonce, CreatedAt: time.Now().UTC()}
+	err = r.withTx(txSpec{Name: "schedule feed token", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		var exists bool
+		if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists); err != nil {
+			return fmt.Errorf("load user %s: %w", userID, err)
+		}
+		if !exists {
+			return notFound("user", userID)
+		}
+		if _, err := tx.Exec(ctx, "INSERT INTO schedule_feed_tokens ("+scheduleFeedTokenColumns+") VALUES ($1, $2, $3) ON CONFLICT (user_id) DO UPDATE SET nonce = EXCLUDED.nonce, created_at = EXCLUDED.created_at",
+			token.UserID,
+			token.Nonce,
+			token.CreatedAt,
+		); err != nil {
+			return fmt.Errorf("store schedule feed token: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.ScheduleFeedToken{}, err
+	}
+	return token, nil
+}
+
+// GetScheduleFeedToken returns the user's current feed token, if any.
+func (r *postgresRepository) GetScheduleFeedToken(userID string) (models.ScheduleFeedToken, bool) {
+	if r == nil || r.pool == nil {
+		return models.ScheduleFeedToken{}, false
+	}
+	var (
+		token models.ScheduleFeedToken
+		found bool
+	)
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		loaded, err := scanScheduleFeedToken(conn.QueryRow(ctx, "SELECT "+scheduleFeedTokenColumns+" FROM schedule_feed_tokens WHERE user_id = $1", userID))
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return nil
+			}
+			return fmt.Errorf("load schedule feed token: %w", err)
+		}
+		token, found = loaded, true
+		return nil
+	})
+	if err != nil {
+		return models.ScheduleFeedToken{}, false
+	}
+	return token, found
+}
+
+// RevokeScheduleFeedToken deletes the user's feed token. Revoking a user
+// without one succeeds.
+func (r *postgresRepository) RevokeScheduleFeedToken(userID string) error {
+	if r == nil || r.pool == nil {
+		return ErrPostgresUnavailable
+	}
+	return r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		if _, err := conn.Exec(ctx, "DELETE FROM schedule_feed_tokens WHERE user_id = $1", userID); err != nil {
+			return fmt.Errorf("delete schedule feed token: %w", err)
+		}
+		return nil
+	})
+}
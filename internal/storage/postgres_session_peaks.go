@@ -0,0 +1,47 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgtype"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func (r *postgresRepository) UpdateSessionPeak(sessionID string, concurrent int) (int, error) {
+	if r == nil || r.pool == nil {
+		return 0, ErrPostgresUnavailable
+	}
+	if concurrent < 0 {
+		return 0, invalid("concurrent", "concurrent viewers cannot be negative")
+	}
+	var peak int
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		// GREATEST keeps the update monotonic without a read first, so racing
+		// writers cannot lower a peak another has just raised.
+		err := conn.QueryRow(ctx, "UPDATE stream_sessions SET peak_concurrent = GREATEST(peak_concurrent, $2) WHERE id = $1 AND ended_at IS NULL RETURNING peak_concurrent", sessionID, concurrent).Scan(&peak)
+		if err == nil {
+			return nil
+		}
+		if !errors.Is(err, pgx.ErrNoRows) {
+			return fmt.Errorf("update session %s peak: %w", sessionID, err)
+		}
+		var endedAt pgtype.Timestamptz
+		if err := conn.QueryRow(ctx, "SELECT peak_concurrent, ended_at FROM stream_sessions WHERE id = $1", sessionID).Scan(&peak, &endedAt); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return notFound("stream session", sessionID)
+			}
+			return fmt.Errorf("load session %s: %w", sessionID, err)
+		}
+		return ErrStreamSessionEnded
+	})
+	if err != nil {
+		if errors.Is(err, ErrStreamSessionEnded) {
+			return peak, err
+		}
+		return 0, err
+	}
+	return peak, nil
+}
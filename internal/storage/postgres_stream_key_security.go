@@ -0,0 +1,321 @@
+package storage
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"bitriver-live/internal/models"
+)
+
+const securityEventColumns = "id, channel_id, kind, source_ip, session_id, detail, created_at"
+
+const notificationColumns = "id, user_id, category, kind, channel_id, message, metadata, created_at"
+
+func scanSecurityEvent(row pgx.Row) (models.SecurityEvent, error) {
+	var event models.SecurityEvent
+	if err := row.Scan(&event.ID, &event.ChannelID, &event.Kind, &event.SourceIP, &event.SessionID, &event.Detail, &event.CreatedAt); err != nil {
+		return models.SecurityEvent{}, err
+	}
+	event.CreatedAt = event.CreatedAt.UTC()
+	return event, nil
+}
+
+func scanNotification(row pgx.Row) (models.Notification, error) {
+	var (
+		notification models.Notification
+		metadataJSON []byte
+	)
+	if err := row.Scan(&notification.ID, &notification.UserID, &notification.Category, &notification.Kind, &notification.ChannelID, &notification.Message, &metadataJSON, &notification.CreatedAt); err != nil {
+		return models.Notification{}, err
+	}
+	if len(metadataJSON) > 0 {
+		if err := json.Unmarshal(metadataJSON, &notification.Metadata); err != nil {
+			return models.Notification{}, fmt.Errorf("decode notification %s metadata: %w", notification.ID, err)
+		}
+	}
+	notification.CreatedAt = notification.CreatedAt.UTC()
+	return cloneNotification(notification), nil
+}
+
+func encodeNotificationMetadata(metadata map[string]string) ([]byte, error) {
+	if metadata == nil {
+		metadata = map[string]string{}
+	}
+	data, err := json.Marshal(metadata)
+	if err != nil {
+		return nil, fmt.Errorf("encode notification metadata: %w", err)
+	}
+	return data, nil
+}
+
+func (r *postgresRepository) ClaimPublishSource(channelID, source string) (models.StreamSession, error) {
+	if r == nil || r.pool == nil {
+		return models.StreamSession{}, ErrPostgresUnavailable
+	}
+	source = strings.TrimSpace(source)
+	var (
+		sessionID string
+		conflict  bool
+	)
+	err := r.withTx(txSpec{Name: "claim publish source", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		conflict = false
+		var current *string
+		if err := tx.QueryRow(ctx, "SELECT current_session_id FROM channels WHERE id = $1", channelID).Scan(&current); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return notFound("channel", channelID)
+			}
+			return fmt.Errorf("load channel %s: %w", channelID, err)
+		}
+		if current == nil {
+			return ErrChannelNotLive
+		}
+		sessionID = *current
+		var claimed string
+		if err := tx.QueryRow(ctx, "SELECT publish_source FROM stream_sessions WHERE id = $1 FOR UPDATE", sessionID).Scan(&claimed); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return fmt.Errorf("session %s missing", sessionID)
+			}
+			return fmt.Errorf("lock session %s: %w", sessionID, err)
+		}
+		switch {
+		case source == "" || claimed == source:
+			return nil
+		case claimed != "":
+			conflict = true
+			return nil
+		}
+		if _, err := tx.Exec(ctx, "UPDATE stream_sessions SET publish_source = $1 WHERE id = $2", source, sessionID); err != nil {
+			return fmt.Errorf("update session %s: %w", sessionID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.StreamSession{}, err
+	}
+	loadCtx, cancel := r.acquireContext()
+	defer cancel()
+	session, ok := r.loadStreamSession(loadCtx, sessionID)
+	if !ok {
+		return models.StreamSession{}, fmt.Errorf("session %s missing", sessionID)
+	}
+	if conflict {
+		return session, ErrPublishSourceConflict
+	}
+	return session, nil
+}
+
+func (r *postgresRepository) CreateSecurityEvent(params SecurityEventParams) (models.SecurityEvent, error) {
+	if r == nil || r.pool == nil {
+		return models.SecurityEvent{}, ErrPostgresUnavailable
+	}
+	event, err := newSecurityEvent(params)
+	if err != nil {
+		return models.SecurityEvent{}, err
+	}
+	err = r.withTx(txSpec{Name: "create security event", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		if err := ensureChannelExists(ctx, tx, event.ChannelID); err != nil {
+			return err
+		}
+		if _, err := tx.Exec(ctx, "INSERT INTO security_events ("+securityEventColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7)",
+			event.ID, event.ChannelID, event.Kind, event.SourceIP, event.SessionID, event.Detail, event.CreatedAt); err != nil {
+			return fmt.Errorf("insert security event: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.SecurityEvent{}, err
+	}
+	return event, nil
+}
+
+func (r *postgresRepository) ListSecurityEvents(channelID string, since time.Time) ([]models.SecurityEvent, error) {
+	if r == nil || r.pool == nil {
+		return nil, ErrPostgresUnavailable
+	}
+	events := make([]models.SecurityEvent, 0)
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		var exists bool
+		if err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM channels WHERE id = $1)", channelID).Scan(&exists); err != nil {
+			return fmt.Errorf("check channel %s: %w", channelID, err)
+		}
+		if !exists {
+			return notFound("channel", channelID)
+		}
+		rows, err := conn.Query(ctx, "SELECT "+securityEventColumns+" FROM security_events WHERE channel_id = $1 AND created_at >= $2 ORDER BY created_at DESC, id", channelID, since.UTC())
+		if err != nil {
+			return fmt.Errorf("list security events: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			event, err := scanSecurityEvent(rows)
+			if err != nil {
+				return fmt.Errorf("scan security event: %w", err)
+			}
+			events = append(events, event)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (r *postgresRepository) CreateNotification(params NotificationParams) (models.Notification, error) {
+	if r == nil || r.pool == nil {
+		return models.Notification{}, ErrPostgresUnavailable
+	}
+	notification, err := newNotification(params)
+	if err != nil {
+		return models.Notification{}, err
+	}
+	metadata, err := encodeNotificationMetadata(notification.Metadata)
+	if err != nil {
+		return models.Notification{}, err
+	}
+	err = r.withTx(txSpec{Name: "create notification", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		var exists bool
+		if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)", notification.UserID).Scan(&exists); err != nil {
+			return fmt.Errorf("check user %s: %w", notification.UserID, err)
+		}
+		if !exists {
+			return notFound("user", notification.UserID)
+		}
+		if _, err := tx.Exec(ctx, "INSERT INTO notifications ("+notificationColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8)",
+			notification.ID, notification.UserID, notification.Category, notification.Kind, notification.ChannelID, notification.Message, metadata, notification.CreatedAt); err != nil {
+			return fmt.Errorf("insert notification: %w", err)
+		}
+		return nil
+	})
+	if err != nil {
+		return models.Notification{}, err
+	}
+	return notification, nil
+}
+
+func (r *postgresRepository) ListNotifications(userID string, limit int) ([]models.Notification, error) {
+	if r == nil || r.pool == nil {
+		return nil, ErrPostgresUnavailable
+	}
+	notifications := make([]models.Notification, 0)
+	err := r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		var exists bool
+		if err := conn.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE id = $1)", userID).Scan(&exists); err != nil {
+			return fmt.Errorf("check user %s: %w", userID, err)
+		}
+		if !exists {
+			return notFound("user", userID)
+		}
+		query := "SELECT " + notificationColumns + " FROM notifications WHERE user_id = $1 ORDER BY created_at DESC, id"
+		args := []any{userID}
+		if limit > 0 {
+			query += " LIMIT $2"
+			args = append(args, limit)
+		}
+		rows, err := conn.Query(ctx, query, args...)
+		if err != nil {
+			return fmt.Errorf("list notifications: %w", err)
+		}
+		defer rows.Close()
+		for rows.Next() {
+			notification, err := scanNotification(rows)
+			if err != nil {
+				return fmt.Errorf("scan notification: %w", err)
+			}
+			notifications = append(notifications, notification)
+		}
+		return rows.Err()
+	})
+	if err != nil {
+		return nil, err
+	}
+	return notifications, nil
+}
+
+func exportSnapshotSecurityEvents(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	rows, err := tx.Query(ctx, "SELECT "+securityEventColumns+" FROM security_events")
+	if err != nil {
+		return fmt.Errorf("export security events: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		event, err := scanSecurityEvent(rows)
+		if err != nil {
+			return fmt.Errorf("scan security event: %w", err)
+		}
+		snapshot.SecurityEvents[event.ID] = event
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate security events: %w", err)
+	}
+	return nil
+}
+
+func exportSnapshotNotifications(ctx context.Context, tx pgx.Tx, snapshot *Snapshot) error {
+	rows, err := tx.Query(ctx, "SELECT "+notificationColumns+" FROM notifications")
+	if err != nil {
+		return fmt.Errorf("export notifications: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		notification, err := scanNotification(rows)
+		if err != nil {
+			return fmt.Errorf("scan notification: %w", err)
+		}
+		snapshot.Notifications[notification.ID] = notification
+	}
+	if err := rows.Err(); err != nil {
+		return fmt.Errorf("iterate notifications: %w", err)
+	}
+	return nil
+}
+
+func (r *postgresRepository) importSnapshotSecurityEvents(ctx context.Context, im *snapshotImporter, events map[string]models.SecurityEvent) error {
+	for _, id := range sortedSnapshotKeys(events) {
+		event := events[id]
+		_, err := im.exec(ctx, "security_events", id, "INSERT INTO security_events ("+securityEventColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7) ON CONFLICT (id) DO NOTHING",
+			id,
+			strings.TrimSpace(event.ChannelID),
+			event.Kind,
+			event.SourceIP,
+			event.SessionID,
+			event.Detail,
+			event.CreatedAt.UTC(),
+		)
+		if err != nil {
+			return fmt.Errorf("insert security event %s: %w", id, err)
+		}
+	}
+	return nil
+}
+
+func (r *postgresRepository) importSnapshotNotifications(ctx context.Context, im *snapshotImporter, notifications map[string]models.Notification) error {
+	for _, id := range sortedSnapshotKeys(notifications) {
+		notification := notifications[id]
+		metadata, err := encodeNotificationMetadata(notification.Metadata)
+		if err != nil {
+			return fmt.Errorf("notification %s: %w", id, err)
+		}
+		_, err = im.exec(ctx, "notifications", id, "INSERT INTO notifications ("+notificationColumns+") VALUES ($1, $2, $3, $4, $5, $6, $7, $8) ON CONFLICT (id) DO NOTHING",
+			id,
+			strings.TrimSpace(notification.UserID),
+			notification.Category,
+			notification.Kind,
+			notification.ChannelID,
+			notification.Message,
+			metadata,
+			notification.CreatedAt.UTC(),
+		)
+		if err != nil {
+			return fmt.Errorf("insert notification %s: %w", id, err)
+		}
+	}
+	return nil
+}
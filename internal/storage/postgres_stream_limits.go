@@ -0,0 +1,127 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/jackc/pgx/v5"
+
+	"bitriver-live/internal/models"
+)
+
+// streamSlotLockClass namespaces the advisory locks that serialize stream
+// starts per owner, so they cannot collide with other advisory locks.
+const streamSlotLockClass int32 = 0x42524c53
+
+// queryStreamSlots returns the owner's channels, other than exclude, that
+// hold a slot, ordered by channel ID.
+func queryStreamSlots(ctx context.Context, tx pgx.Tx, ownerID, exclude string) ([]StreamSlot, error) {
+	rows, err := tx.Query(ctx, "SELECT id, title, live_state FROM channels WHERE owner_id = $1 AND current_session_id IS NOT NULL AND id <> $2 ORDER BY id", ownerID, exclude)
+	if err != nil {
+		return nil, fmt.Errorf("list streaming channels for %s: %w", ownerID, err)
+	}
+	defer rows.Close()
+	slots := make([]StreamSlot, 0)
+	for rows.Next() {
+		var slot StreamSlot
+		if err := rows.Scan(&slot.ChannelID, &slot.Title, &slot.LiveState); err != nil {
+			return nil, fmt.Errorf("scan streaming channel: %w", err)
+		}
+		slots = append(slots, slot)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("iterate streaming channels: %w", err)
+	}
+	return slots, nil
+}
+
+// checkStreamLimit refuses to start channelID when its owner is at their
+// limit. It takes a transaction-scoped advisory lock on the owner first, so
+// starts on the owner's other channels wait until this transaction has
+// reserved its session or given up, and cannot both take the last slot.
+func (r *postgresRepository) checkStreamLimit(ctx context.Context, tx pgx.Tx, ownerID, channelID string) error {
+	var override *int
+	if err := tx.QueryRow(ctx, "SELECT max_concurrent_streams FROM users WHERE id = $1", ownerID).Scan(&override); err != nil {
+		if errors.Is(err, pgx.ErrNoRows) {
+			return nil
+		}
+		return fmt.Errorf("load stream limit for %s: %w", ownerID, err)
+	}
+	limit := effectiveStreamLimit(override, r.streamLimit)
+	if limit <= 0 {
+		return nil
+	}
+	if _, err := tx.Exec(ctx, "SELECT pg_advisory_xact_lock($1, hashtext($2))", streamSlotLockClass, ownerID); err != nil {
+		return fmt.Errorf("lock stream slots for %s: %w", ownerID, err)
+	}
+	active, err := queryStreamSlots(ctx, tx, ownerID, channelID)
+	if err != nil {
+		return err
+	}
+	return checkStreamLimit(limit, active)
+}
+
+// StreamSlotUsage reports the user's concurrent streams and the limit they
+// are held to.
+func (r *postgresRepository) StreamSlotUsage(userID string) (StreamSlotReport, error) {
+	if r == nil || r.pool == nil {
+		return StreamSlotReport{}, ErrPostgresUnavailable
+	}
+	var report StreamSlotReport
+	err := r.withTx(txSpec{Name: "stream slot usage", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		var override *int
+		if err := tx.QueryRow(ctx, "SELECT max_concurrent_streams FROM users WHERE id = $1", userID).Scan(&override); err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return notFound("user", userID)
+			}
+			return fmt.Errorf("load user %s: %w", userID, err)
+		}
+		active, err := queryStreamSlots(ctx, tx, userID, "")
+		if err != nil {
+			return err
+		}
+		report = StreamSlotReport{
+			UserID:        userID,
+			Limit:         effectiveStreamLimit(override, r.streamLimit),
+			LimitOverride: override,
+			Active:        active,
+		}
+		return nil
+	})
+	if err != nil {
+		return StreamSlotReport{}, err
+	}
+	return report, nil
+}
+
+// SetUserStreamLimit sets the admin override of the user's concurrent
+// stream limit. Nil returns the user to the platform default and zero lifts
+// the limit. Lowering the limit does not stop streams already running; it
+// only refuses new starts.
+func (r *postgresRepository) SetUserStreamLimit(userID string, limit *int) (models.User, error) {
+	if r == nil || r.pool == nil {
+		return models.User{}, ErrPostgresUnavailable
+	}
+	override, err := normalizeStreamLimit(limit)
+	if err != nil {
+		return models.User{}, err
+	}
+	var user models.User
+	err = r.withTx(txSpec{Name: "set stream limit", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, "UPDATE users SET max_concurrent_streams = $2 WHERE id = $1 RETURNING id, display_name, email, roles, password_hash, self_signup, created_at, birth_date, age_confirmed_at, deactivated_at, username, username_changed_at, username_grace, max_concurrent_streams", userID, override)
+		updated, err := scanUser(row)
+		if err != nil {
+			if errors.Is(err, pgx.ErrNoRows) {
+				return notFound("user", userID)
+			}
+			return fmt.Errorf("update stream limit for %s: %w", userID, err)
+		}
+		user = updated
+		return nil
+	})
+	if err != nil {
+		return models.User{}, err
+	}
+	return user, nil
+}
@@ -0,0 +1,186 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math/rand"
+	"strconv"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+	"github.com/jackc/pgx/v5/pgxpool"
+
+	"bitriver-live/internal/observability/metrics"
+)
+
+const (
+	// defaultStatementTimeout bounds each statement of a repository
+	// transaction so a lock wait or slow query cannot hold it open forever.
+	defaultStatementTimeout = 30 * time.Second
+	// defaultTxMaxRetries is how many times a retry-safe transaction is rerun
+	// after a serialization failure or deadlock.
+	defaultTxMaxRetries = 3
+	// txRetryBaseDelay is the first retry's upper bound; each later retry
+	// doubles it. The actual delay is drawn uniformly below the bound.
+	txRetryBaseDelay = 10 * time.Millisecond
+)
+
+// SQLSTATE codes the transaction helper acts on.
+const (
+	sqlStateSerializationFailure = "40001"
+	sqlStateDeadlockDetected     = "40P01"
+	sqlStateQueryCanceled        = "57014"
+	sqlStateLockNotAvailable     = "55P03"
+)
+
+// ErrStatementTimeout indicates that a datastore statement was cancelled
+// because it ran past the statement timeout or the caller's deadline.
+var ErrStatementTimeout = errors.New("datastore statement timed out")
+
+// txSpec describes a repository transaction. Name labels errors and
+// metrics. RetrySafe marks closures that only touch the database and assign
+// their results afresh on each run, so rerunning them after a serialization
+// failure or deadlock is harmless.
+type txSpec struct {
+	Name      string
+	RetrySafe bool
+	Options   pgx.TxOptions
+}
+
+// txBeginner is satisfied by pooled connections; tests substitute fakes.
+type txBeginner interface {
+	BeginTx(ctx context.Context, opts pgx.TxOptions) (pgx.Tx, error)
+}
+
+func normalizeStatementTimeout(timeout time.Duration) time.Duration {
+	if timeout <= 0 {
+		return defaultStatementTimeout
+	}
+	return timeout
+}
+
+// sqlState returns the SQLSTATE of a server error wrapped in err.
+func sqlState(err error) string {
+	var pgErr *pgconn.PgError
+	if errors.As(err, &pgErr) {
+		return pgErr.SQLState()
+	}
+	return ""
+}
+
+// isRetryableTxError reports whether err aborted the transaction only
+// because of a conflict with a concurrent one.
+func isRetryableTxError(err error) bool {
+	switch sqlState(err) {
+	case sqlStateSerializationFailure, sqlStateDeadlockDetected:
+		return true
+	}
+	return false
+}
+
+// isStatementTimeout reports whether err is Postgres cancelling a statement
+// for running past statement_timeout or lock_timeout.
+func isStatementTimeout(err error) bool {
+	switch sqlState(err) {
+	case sqlStateQueryCanceled, sqlStateLockNotAvailable:
+		return true
+	}
+	return false
+}
+
+// statementTimeout returns the statement_timeout for a transaction run under
+// ctx. When the acquire timeout left a deadline shorter than the configured
+// timeout, the remaining time is used so Postgres cancels the statement
+// itself instead of the client abandoning the connection mid-query.
+func (r *postgresRepository) statementTimeout(ctx context.Context) time.Duration {
+	timeout := normalizeStatementTimeout(r.cfg.StatementTimeout)
+	if deadline, ok := ctx.Deadline(); ok {
+		if remaining := time.Until(deadline); remaining < timeout {
+			timeout = remaining
+		}
+	}
+	return timeout
+}
+
+// deadlineBound reports whether the deadline on ctx, rather than the
+// configured statement timeout, is what limits the transaction now.
+func (r *postgresRepository) deadlineBound(ctx context.Context) bool {
+	deadline, ok := ctx.Deadline()
+	return ok && time.Until(deadline) < normalizeStatementTimeout(r.cfg.StatementTimeout)
+}
+
+// withTx acquires a connection and runs fn in a transaction on it with
+// runTx.
+func (r *postgresRepository) withTx(spec txSpec, fn func(context.Context, pgx.Tx) error) error {
+	return r.withConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		return r.runTx(ctx, conn, spec, fn)
+	})
+}
+
+// runTx runs fn in a transaction on conn and commits it when fn succeeds.
+// Every statement is bounded by the statement timeout, set with SET LOCAL so
+// it ends with the transaction. When fn or the commit fails with a
+// serialization failure or deadlock and spec.RetrySafe is set, the whole
+// transaction is rerun up to the configured number of retries after a
+// jittered, growing delay. Retries and timeouts are counted in the datastore
+// transaction metric.
+func (r *postgresRepository) runTx(ctx context.Context, conn txBeginner, spec txSpec, fn func(context.Context, pgx.Tx) error) error {
+	retries := 0
+	if spec.RetrySafe && r.cfg.TxMaxRetries > 0 {
+		retries = r.cfg.TxMaxRetries
+	}
+	for attempt := 0; ; attempt++ {
+		err := r.runTxOnce(ctx, conn, spec, fn)
+		if err == nil {
+			return nil
+		}
+		if isStatementTimeout(err) || errors.Is(err, context.DeadlineExceeded) {
+			metrics.Default().ObserveDatastoreTx(spec.Name, "timeout")
+			if r.deadlineBound(ctx) && !errors.Is(err, context.DeadlineExceeded) {
+				// Postgres cancelled the statement on the caller's behalf.
+				return fmt.Errorf("%w: %w: %w", ErrStatementTimeout, context.DeadlineExceeded, err)
+			}
+			return fmt.Errorf("%w: %w", ErrStatementTimeout, err)
+		}
+		if !isRetryableTxError(err) || attempt >= retries {
+			return err
+		}
+		metrics.Default().ObserveDatastoreTx(spec.Name, "retry")
+		bound := txRetryBaseDelay << attempt
+		timer := time.NewTimer(time.Duration(rand.Int63n(int64(bound)) + 1))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return fmt.Errorf("%s: %w (retry abandoned: %v)", spec.Name, err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+func (r *postgresRepository) runTxOnce(ctx context.Context, conn txBeginner, spec txSpec, fn func(context.Context, pgx.Tx) error) error {
+	timeout := r.statementTimeout(ctx)
+	if timeout <= 0 {
+		return fmt.Errorf("begin %s: %w", spec.Name, context.DeadlineExceeded)
+	}
+	tx, err := conn.BeginTx(ctx, spec.Options)
+	if err != nil {
+		return fmt.Errorf("begin %s: %w", spec.Name, err)
+	}
+	defer rollbackTx(ctx, tx)
+
+	// SET cannot take bind parameters; set_config with is_local does the
+	// same as SET LOCAL.
+	millis := strconv.FormatInt(max(timeout.Milliseconds(), 1), 10)
+	if _, err := tx.Exec(ctx, "SELECT set_config('statement_timeout', $1, true)", millis); err != nil {
+		return fmt.Errorf("set statement timeout for %s: %w", spec.Name, err)
+	}
+	if err := fn(ctx, tx); err != nil {
+		return err
+	}
+	if err := tx.Commit(ctx); err != nil {
+		return fmt.Errorf("commit %s: %w", spec.Name, err)
+	}
+	return nil
+}
@@ -0,0 +1,227 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+
+	"bitriver-live/internal/observability/metrics"
+)
+
+// fakeTx records the statements and outcome of one transaction. Methods the
+// helper does not call fall through to the nil embedded interface.
+type fakeTx struct {
+	pgx.Tx
+	execs      []string
+	args       [][]any
+	commitErr  error
+	committed  bool
+	rolledBack bool
+}
+
+func (tx *fakeTx) Exec(_ context.Context, sql string, args ...any) (pgconn.CommandTag, error) {
+	tx.execs = append(tx.execs, sql)
+	tx.args = append(tx.args, args)
+	return pgconn.CommandTag{}, nil
+}
+
+func (tx *fakeTx) Commit(context.Context) error {
+	if tx.commitErr != nil {
+		return tx.commitErr
+	}
+	tx.committed = true
+	return nil
+}
+
+func (tx *fakeTx) Rollback(context.Context) error {
+	if tx.committed {
+		return pgx.ErrTxClosed
+	}
+	tx.rolledBack = true
+	return nil
+}
+
+type fakeTxBeginner struct {
+	txs []*fakeTx
+}
+
+func (b *fakeTxBeginner) BeginTx(context.Context, pgx.TxOptions) (pgx.Tx, error) {
+	tx := &fakeTx{}
+	b.txs = append(b.txs, tx)
+	return tx, nil
+}
+
+func newTxTestRepository(opts ...Option) *postgresRepository {
+	return &postgresRepository{cfg: newPostgresConfig("", opts...)}
+}
+
+func TestRunTxRetriesSerializationFailure(t *testing.T) {
+	repo := newTxTestRepository()
+	conn := &fakeTxBeginner{}
+	label := metrics.DatastoreTxLabel{Operation: "test retry", Event: "retry"}
+	before := metrics.Default().DatastoreTxCounts()[label]
+
+	calls := 0
+	err := repo.runTx(context.Background(), conn, txSpec{Name: "test retry", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		calls++
+		if calls < 3 {
+			return fmt.Errorf("update row: %w", &pgconn.PgError{Code: sqlStateSerializationFailure})
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("runTx: %v", err)
+	}
+	if calls != 3 {
+		t.Fatalf("expected 3 attempts, got %d", calls)
+	}
+	if len(conn.txs) != 3 {
+		t.Fatalf("expected 3 transactions, got %d", len(conn.txs))
+	}
+	for i, tx := range conn.txs[:2] {
+		if tx.committed || !tx.rolledBack {
+			t.Fatalf("attempt %d: expected rollback without commit", i+1)
+		}
+	}
+	if !conn.txs[2].committed {
+		t.Fatal("expected final attempt to commit")
+	}
+	if after := metrics.Default().DatastoreTxCounts()[label]; after != before+2 {
+		t.Fatalf("expected 2 retries recorded, got %d", after-before)
+	}
+}
+
+func TestRunTxRetriesDeadlockOnCommit(t *testing.T) {
+	repo := newTxTestRepository()
+	conn := &fakeTxBeginner{}
+
+	calls := 0
+	err := repo.runTx(context.Background(), conn, txSpec{Name: "test commit", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		calls++
+		if calls == 1 {
+			tx.(*fakeTx).commitErr = &pgconn.PgError{Code: sqlStateDeadlockDetected}
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("runTx: %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 attempts, got %d", calls)
+	}
+}
+
+func TestRunTxSurfacesNonRetryableErrors(t *testing.T) {
+	repo := newTxTestRepository()
+	conn := &fakeTxBeginner{}
+	uniqueViolation := &pgconn.PgError{Code: "23505"}
+
+	calls := 0
+	err := repo.runTx(context.Background(), conn, txSpec{Name: "test unique", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		calls++
+		return uniqueViolation
+	})
+	if !errors.Is(err, uniqueViolation) {
+		t.Fatalf("expected unique violation, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected a single attempt, got %d", calls)
+	}
+	if conn.txs[0].committed || !conn.txs[0].rolledBack {
+		t.Fatal("expected failed transaction to roll back")
+	}
+}
+
+func TestRunTxDoesNotRetryUnsafeClosures(t *testing.T) {
+	repo := newTxTestRepository()
+	conn := &fakeTxBeginner{}
+
+	calls := 0
+	err := repo.runTx(context.Background(), conn, txSpec{Name: "test unsafe"}, func(ctx context.Context, tx pgx.Tx) error {
+		calls++
+		return &pgconn.PgError{Code: sqlStateSerializationFailure}
+	})
+	if !isRetryableTxError(err) {
+		t.Fatalf("expected serialization failure, got %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected a single attempt, got %d", calls)
+	}
+}
+
+func TestRunTxRetryLimit(t *testing.T) {
+	repo := newTxTestRepository(WithPostgresTxRetries(1))
+	conn := &fakeTxBeginner{}
+
+	calls := 0
+	err := repo.runTx(context.Background(), conn, txSpec{Name: "test limit", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		calls++
+		return &pgconn.PgError{Code: sqlStateDeadlockDetected}
+	})
+	if !isRetryableTxError(err) {
+		t.Fatalf("expected deadlock error, got %v", err)
+	}
+	if calls != 2 {
+		t.Fatalf("expected 2 attempts, got %d", calls)
+	}
+}
+
+func TestRunTxStatementTimeout(t *testing.T) {
+	repo := newTxTestRepository(WithPostgresStatementTimeout(250 * time.Millisecond))
+	conn := &fakeTxBeginner{}
+	label := metrics.DatastoreTxLabel{Operation: "test timeout", Event: "timeout"}
+	before := metrics.Default().DatastoreTxCounts()[label]
+
+	calls := 0
+	err := repo.runTx(context.Background(), conn, txSpec{Name: "test timeout", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		calls++
+		return &pgconn.PgError{Code: sqlStateQueryCanceled, Message: "canceling statement due to statement timeout"}
+	})
+	if !errors.Is(err, ErrStatementTimeout) {
+		t.Fatalf("expected ErrStatementTimeout, got %v", err)
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected configured timeout, not a deadline, to be reported: %v", err)
+	}
+	if calls != 1 {
+		t.Fatalf("expected a single attempt, got %d", calls)
+	}
+	tx := conn.txs[0]
+	if len(tx.execs) == 0 || tx.execs[0] != "SELECT set_config('statement_timeout', $1, true)" {
+		t.Fatalf("expected statement timeout to be set first, got %v", tx.execs)
+	}
+	if got := tx.args[0][0]; got != "250" {
+		t.Fatalf("expected statement_timeout 250, got %v", got)
+	}
+	if after := metrics.Default().DatastoreTxCounts()[label]; after != before+1 {
+		t.Fatalf("expected 1 timeout recorded, got %d", after-before)
+	}
+}
+
+func TestRunTxStatementTimeoutFollowsDeadline(t *testing.T) {
+	repo := newTxTestRepository(WithPostgresStatementTimeout(time.Minute))
+	conn := &fakeTxBeginner{}
+	ctx, cancel := context.WithTimeout(context.Background(), 500*time.Millisecond)
+	defer cancel()
+
+	err := repo.runTx(ctx, conn, txSpec{Name: "test deadline"}, func(ctx context.Context, tx pgx.Tx) error {
+		return &pgconn.PgError{Code: sqlStateQueryCanceled}
+	})
+	if !errors.Is(err, ErrStatementTimeout) || !errors.Is(err, context.DeadlineExceeded) {
+		t.Fatalf("expected statement timeout caused by the deadline, got %v", err)
+	}
+	arg, _ := conn.txs[0].args[0][0].(string)
+	millis, convErr := strconv.Atoi(arg)
+	if convErr != nil {
+		t.Fatalf("parse statement_timeout %q: %v", arg, convErr)
+	}
+	if millis <= 0 || millis > 500 {
+		t.Fatalf("expected statement_timeout clamped to the deadline, got %dms", millis)
+	}
+}
@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"bitriver-live/internal/models"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// postgresUsernameTaken checks usernames against users_username_lower_idx
+// within tx, skipping the user exceptID.
+func postgresUsernameTaken(ctx context.Context, tx pgx.Tx, exceptID string) func(key string) (bool, error) {
+	return func(key string) (bool, error) {
+		var taken bool
+		if err := tx.QueryRow(ctx, "SELECT EXISTS (SELECT 1 FROM users WHERE lower(username) = $1 AND id <> $2)", key, exceptID).Scan(&taken); err != nil {
+			return false, fmt.Errorf("check username: %w", err)
+		}
+		return taken, nil
+	}
+}
+
+func (r *postgresRepository) FindUserByUsername(username string) (models.User, bool) {
+	key := usernameKey(username)
+	if r == nil || r.pool == nil || key == "" {
+		return models.User{}, false
+	}
+
+	var user models.User
+	err := r.withReadConn(func(ctx context.Context, conn *pgxpool.Conn) error {
+		row := conn.QueryRow(ctx, "SELECT id, display_name, email, roles, password_hash, self_signup, created_at, birth_date, age_confirmed_at, deactivated_at, username, username_changed_at, username_grace, max_concurrent_streams FROM users WHERE lower(username) = $1", key)
+		scanned, scanErr := scanUser(row)
+		if scanErr != nil {
+			return scanErr
+		}
+		user = scanned
+		return nil
+	})
+	if err != nil {
+		return models.User{}, false
+	}
+	return user, true
+}
+
+func (r *postgresRepository) ChangeUsername(id, username string, now time.Time) (models.User, error) {
+	if r == nil || r.pool == nil {
+		return models.User{}, ErrPostgresUnavailable
+	}
+	username, err := NormalizeUsername(username)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	var updated models.User
+	updateErr := r.withTx(txSpec{Name: "change username", RetrySafe: true}, func(ctx context.Context, tx pgx.Tx) error {
+		row := tx.QueryRow(ctx, "SELECT id, display_name, email, roles, password_hash, self_signup, created_at, birth_date, age_confirmed_at, deactivated_at, username, username_changed_at, username_grace, max_concurrent_streams FROM users WHERE id = $1 FOR UPDATE", id)
+		user, err := scanUser(row)
+		if errors.Is(err, pgx.ErrNoRows) {
+			return notFound("user", id)
+		}
+		if err != nil {
+			return fmt.Errorf("load user %s: %w", id, err)
+		}
+		if user.Username == username {
+			updated = user
+			return nil
+		}
+		if err := checkUsernameCooldown(user, now); err != nil {
+			return err
+		}
+		taken, err := postgresUsernameTaken(ctx, tx, id)(usernameKey(username))
+		if err != nil {
+			return err
+		}
+		if taken {
+			return ErrUsernameTaken
+		}
+
+		changedAt := now.UTC()
+		if _, err := tx.Exec(ctx, "UPDATE users SET username = $1, username_changed_at = $2, username_grace = FALSE WHERE id = $3", username, changedAt, id); err != nil {
+			return fmt.Errorf("update username: %w", err)
+		}
+		user.Username = username
+		user.UsernameChangedAt = &changedAt
+		user.UsernameGrace = false
+		updated = user
+		return nil
+	})
+	if updateErr != nil {
+		return models.User{}, updateErr
+	}
+	return updated, nil
+}
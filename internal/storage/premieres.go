@@ -0,0 +1,75 @@
+package storage
+
+import (
+	"sort"
+	"time"
+
+	"bitriver-live/internal/models"
+)
+
+// StartDuePremieres publishes the recordings whose premiere is due. Each is
+// published as of its premiere time so a late run does not shift its
+// retention or the playback offset viewers see.
+func (s *Storage) StartDuePremieres(now time.Time) ([]models.Recording, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var due []models.Recording
+	for _, recording := range s.data.Recordings {
+		if recording.PremiereAt == nil || recording.PublishedAt != nil || recording.OverQuota {
+			continue
+		}
+		if recording.PremiereAt.After(now) {
+			continue
+		}
+		due = append(due, recording)
+	}
+	if len(due) == 0 {
+		return []models.Recording{}, nil
+	}
+
+	snapshot := cloneDataset(s.data)
+	started := make([]models.Recording, 0, len(due))
+	for _, recording := range due {
+		updated := cloneRecording(recording)
+		publishedAt := *updated.PremiereAt
+		updated.PublishedAt = &publishedAt
+		updated.RetainUntil = s.recordingDeadline(publishedAt, true)
+		s.data.Recordings[updated.ID] = updated
+		started = append(started, updated)
+	}
+	if err := s.persist(); err != nil {
+		s.data = snapshot
+		return nil, err
+	}
+	sortPremieres(started)
+	for i := range started {
+		started[i] = s.recordingWithClipsLocked(started[i])
+	}
+	return started, nil
+}
+
+// ListPremieringRecordings returns the published recordings whose premiere
+// window contains now.
+func (s *Storage) ListPremieringRecordings(now time.Time) ([]models.Recording, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	premiering := make([]models.Recording, 0)
+	for _, recording := range s.data.Recordings {
+		if recording.PremieringAt(now) {
+			premiering = append(premiering, s.recordingWithClipsLocked(recording))
+		}
+	}
+	sortPremieres(premiering)
+	return premiering, nil
+}
+
+func sortPremieres(recordings []models.Recording) {
+	sort.Slice(recordings, func(i, j int) bool {
+		if !recordings[i].PremiereAt.Equal(*recordings[j].PremiereAt) {
+			return recordings[i].PremiereAt.Before(*recordings[j].PremiereAt)
+		}
+		return recordings[i].ID < recordings[j].ID
+	})
+}
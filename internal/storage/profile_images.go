@@ -0,0 +1,266 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"image"
+	"log/slog"
+	"net/url"
+	"strings"
+	"unicode/utf8"
+
+	"bitriver-live/internal/models"
+)
+
+// ProfileImageKind names the profile image a SetProfileImage call replaces.
+type ProfileImageKind string
+
+const (
+	ProfileImageAvatar ProfileImageKind = "avatar"
+	ProfileImageBanner ProfileImageKind = "banner"
+)
+
+// maxProfileImageURLLength caps avatar and banner URLs set directly on a
+// profile.
+const maxProfileImageURLLength = 2048
+
+// ProfileImageLimits bounds an uploaded avatar or banner.
+type ProfileImageLimits struct {
+	MaxBytes  int
+	MinWidth  int
+	MinHeight int
+	MaxWidth  int
+	MaxHeight int
+}
+
+var profileImageLimits = map[ProfileImageKind]ProfileImageLimits{
+	ProfileImageAvatar: {MaxBytes: 2 << 20, MinWidth: 32, MinHeight: 32, MaxWidth: 2048, MaxHeight: 2048},
+	ProfileImageBanner: {MaxBytes: 5 << 20, MinWidth: 320, MinHeight: 80, MaxWidth: 4096, MaxHeight: 2048},
+}
+
+var (
+	// ErrObjectStorageUnavailable indicates that an operation needs object
+	// storage with a public endpoint and none is configured.
+	ErrObjectStorageUnavailable = errors.New("object storage is not configured")
+	// ErrInvalidProfileImage wraps the reason an uploaded avatar or banner
+	// was refused.
+	ErrInvalidProfileImage = invalid("image", "invalid profile image")
+)
+
+// ProfileImageLimitsFor returns the upload limits for kind and whether kind
+// is a known profile image.
+func ProfileImageLimitsFor(kind ProfileImageKind) (ProfileImageLimits, bool) {
+	limits, ok := profileImageLimits[kind]
+	return limits, ok
+}
+
+// normalizeProfileImageURL validates an avatar or banner URL set on a
+// profile. Empty values clear the image. When cfg restricts profile images,
+// the URL must live under the object storage public endpoint.
+func normalizeProfileImageURL(field, raw string, cfg ObjectStorageConfig) (string, error) {
+	trimmed := strings.TrimSpace(raw)
+	if trimmed == "" {
+		return "", nil
+	}
+	if utf8.RuneCountInString(trimmed) > maxProfileImageURLLength {
+		return "", fmt.Errorf("%s cannot exceed %d characters", field, maxProfileImageURLLength)
+	}
+	parsed, err := url.Parse(trimmed)
+	if err != nil || !parsed.IsAbs() || parsed.Host == "" || (parsed.Scheme != "http" && parsed.Scheme != "https") {
+		return "", fmt.Errorf("%s must be an absolute http or https URL", field)
+	}
+	if cfg.RestrictProfileImages {
+		base := strings.TrimRight(strings.TrimSpace(cfg.PublicEndpoint), "/")
+		if base == "" || !strings.HasPrefix(parsed.String(), base+"/") {
+			return "", fmt.Errorf("%s must be hosted on the configured object storage", field)
+		}
+	}
+	return parsed.String(), nil
+}
+
+// normalizeProfileImageUpdate validates the image URLs in update in place.
+func normalizeProfileImageUpdate(update *ProfileUpdate, cfg ObjectStorageConfig) error {
+	if update.AvatarURL != nil {
+		normalized, err := normalizeProfileImageURL("avatar URL", *update.AvatarURL, cfg)
+		if err != nil {
+			return err
+		}
+		update.AvatarURL = &normalized
+	}
+	if update.BannerURL != nil {
+		normalized, err := normalizeProfileImageURL("banner URL", *update.BannerURL, cfg)
+		if err != nil {
+			return err
+		}
+		update.BannerURL = &normalized
+	}
+	return nil
+}
+
+// inspectProfileImage checks data against the limits for kind and returns
+// its content type and file extension. Only PNG, JPEG, and WebP are
+// accepted.
+func inspectProfileImage(kind ProfileImageKind, data []byte) (string, string, error) {
+	limits, ok := profileImageLimits[kind]
+	if !ok {
+		return "", "", fmt.Errorf("%w: unknown image kind %q", ErrInvalidProfileImage, kind)
+	}
+	if len(data) == 0 {
+		return "", "", fmt.Errorf("%w: image is empty", ErrInvalidProfileImage)
+	}
+	if len(data) > limits.MaxBytes {
+		return "", "", fmt.Errorf("%w: %s cannot exceed %d bytes", ErrInvalidProfileImage, kind, limits.MaxBytes)
+	}
+	var width, height int
+	format := ""
+	if w, h, ok := webpDimensions(data); ok {
+		width, height, format = w, h, "webp"
+	} else if config, decoded, err := image.DecodeConfig(bytes.NewReader(data)); err == nil && (decoded == "png" || decoded == "jpeg") {
+		width, height, format = config.Width, config.Height, decoded
+	} else {
+		return "", "", fmt.Errorf("%w: %s must be a PNG, JPEG, or WebP image", ErrInvalidProfileImage, kind)
+	}
+	if width < limits.MinWidth || height < limits.MinHeight || width > limits.MaxWidth || height > limits.MaxHeight {
+		return "", "", fmt.Errorf("%w: %s must be between %dx%d and %dx%d pixels, got %dx%d", ErrInvalidProfileImage, kind, limits.MinWidth, limits.MinHeight, limits.MaxWidth, limits.MaxHeight, width, height)
+	}
+	return "image/" + format, thumbnailExtension(format), nil
+}
+
+// webpDimensions reads the canvas size from a WebP header. The standard
+// library has no WebP decoder, and the header is all validation needs.
+func webpDimensions(data []byte) (int, int, bool) {
+	if len(data) < 30 || string(data[0:4]) != "RIFF" || string(data[8:12]) != "WEBP" {
+		return 0, 0, false
+	}
+	chunk := data[20:]
+	switch string(data[12:16]) {
+	case "VP8 ":
+		if chunk[3] != 0x9d || chunk[4] != 0x01 || chunk[5] != 0x2a {
+			return 0, 0, false
+		}
+		width := int(binary.LittleEndian.Uint16(chunk[6:8]) & 0x3fff)
+		height := int(binary.LittleEndian.Uint16(chunk[8:10]) & 0x3fff)
+		return width, height, true
+	case "VP8L":
+		if chunk[0] != 0x2f {
+			return 0, 0, false
+		}
+		bits := binary.LittleEndian.Uint32(chunk[1:5])
+		return int(bits&0x3fff) + 1, int((bits>>14)&0x3fff) + 1, true
+	case "VP8X":
+		width := int(chunk[4]) | int(chunk[5])<<8 | int(chunk[6])<<16
+		height := int(chunk[7]) | int(chunk[8])<<8 | int(chunk[9])<<16
+		return width + 1, height + 1, true
+	}
+	return 0, 0, false
+}
+
+// profileImagesEnabled reports whether uploads can produce a public URL.
+func profileImagesEnabled(client objectStorageClient, cfg ObjectStorageConfig) bool {
+	return client != nil && client.Enabled() && strings.TrimSpace(cfg.PublicEndpoint) != ""
+}
+
+// uploadProfileImage validates data and stores it under profiles/{userID}/.
+func uploadProfileImage(client objectStorageClient, cfg ObjectStorageConfig, userID string, kind ProfileImageKind, data []byte) (objectReference, error) {
+	if !profileImagesEnabled(client, cfg) {
+		return objectReference{}, ErrObjectStorageUnavailable
+	}
+	contentType, extension, err := inspectProfileImage(kind, data)
+	if err != nil {
+		return objectReference{}, err
+	}
+	id, err := generateID()
+	if err != nil {
+		return objectReference{}, fmt.Errorf("generate image id: %w", err)
+	}
+	key := buildObjectKey("profiles", userID, string(kind)+"-"+id+"."+extension)
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.uploadTimeout(len(data)))
+	ref, err := client.Upload(ctx, key, contentType, data)
+	cancel()
+	if err != nil {
+		return objectReference{}, fmt.Errorf("upload %s: %w", kind, err)
+	}
+	if ref.URL == "" {
+		return objectReference{}, ErrObjectStorageUnavailable
+	}
+	return ref, nil
+}
+
+// profileImageObjectKey recovers the object key behind imageURL when it is
+// an image previously uploaded for userID. URLs set by hand, or uploaded
+// under another public endpoint, are left alone.
+func profileImageObjectKey(cfg ObjectStorageConfig, userID, imageURL string) (string, bool) {
+	base := strings.TrimRight(strings.TrimSpace(cfg.PublicEndpoint), "/")
+	if base == "" || !strings.HasPrefix(imageURL, base+"/") {
+		return "", false
+	}
+	key := strings.TrimPrefix(imageURL, base+"/")
+	unprefixed := key
+	if prefix := strings.Trim(strings.TrimSpace(cfg.Prefix), "/"); prefix != "" {
+		unprefixed = strings.TrimPrefix(key, prefix+"/")
+	}
+	if !strings.HasPrefix(unprefixed, buildObjectKey("profiles", userID)+"/") {
+		return "", false
+	}
+	return key, true
+}
+
+// deleteReplacedProfileImages removes uploaded avatar and banner objects
+// that after no longer references. Deletion is best-effort: the profile has
+// already been saved, so a failure only leaves an orphaned object behind.
+func deleteReplacedProfileImages(client objectStorageClient, cfg ObjectStorageConfig, before, after models.Profile) {
+	if client == nil || !client.Enabled() {
+		return
+	}
+	for _, pair := range [][2]string{{before.AvatarURL, after.AvatarURL}, {before.BannerURL, after.BannerURL}} {
+		if pair[0] == "" || pair[0] == pair[1] {
+			continue
+		}
+		key, ok := profileImageObjectKey(cfg, before.UserID, pair[0])
+		if !ok {
+			continue
+		}
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.requestTimeout())
+		err := client.Delete(ctx, key)
+		cancel()
+		if err != nil {
+			slog.Warn("delete replaced profile image", "user_id", before.UserID, "key", key, "err", err)
+		}
+	}
+}
+
+// setProfileImage uploads data as userID's avatar or banner and points the
+// profile at it through repo.UpsertProfile, which also removes the image it
+// replaces. The new object is deleted again if the profile cannot be saved.
+func setProfileImage(repo Repository, client objectStorageClient, cfg ObjectStorageConfig, userID string, kind ProfileImageKind, data []byte) (models.Profile, error) {
+	if _, ok := repo.GetUser(userID); !ok {
+		return models.Profile{}, notFound("user", userID)
+	}
+	ref, err := uploadProfileImage(client, cfg, userID, kind, data)
+	if err != nil {
+		return models.Profile{}, err
+	}
+	var update ProfileUpdate
+	if kind == ProfileImageAvatar {
+		update.AvatarURL = &ref.URL
+	} else {
+		update.BannerURL = &ref.URL
+	}
+	profile, err := repo.UpsertProfile(userID, update)
+	if err != nil {
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.requestTimeout())
+		_ = client.Delete(ctx, ref.Key)
+		cancel()
+		return models.Profile{}, err
+	}
+	return profile, nil
+}
+
+// SetProfileImage stores data as the user's avatar or banner in object
+// storage and writes its public URL to the profile.
+func (s *Storage) SetProfileImage(userID string, kind ProfileImageKind, data []byte) (models.Profile, error) {
+	return setProfileImage(s, s.objectClient, s.objectStorage, userID, kind, data)
+}
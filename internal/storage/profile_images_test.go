@@ -0,0 +1,144 @@
+package storage
+
+import (
+	"encoding/binary"
+	"errors"
+	"strings"
+	"testing"
+)
+
+// testWebP builds the header of a lossless WebP image, which is all
+// inspectProfileImage reads.
+func testWebP(width, height int) []byte {
+	data := make([]byte, 30)
+	copy(data[0:], "RIFF")
+	binary.LittleEndian.PutUint32(data[4:], 22)
+	copy(data[8:], "WEBPVP8L")
+	binary.LittleEndian.PutUint32(data[16:], 10)
+	data[20] = 0x2f
+	binary.LittleEndian.PutUint32(data[21:], uint32(width-1)|uint32(height-1)<<14)
+	return data
+}
+
+func TestNormalizeProfileImageURL(t *testing.T) {
+	restricted := ObjectStorageConfig{PublicEndpoint: "https://cdn.example.com/media/", RestrictProfileImages: true}
+	cases := []struct {
+		name  string
+		raw   string
+		cfg   ObjectStorageConfig
+		want  string
+		valid bool
+	}{
+		{name: "empty clears", raw: "  ", valid: true},
+		{name: "https", raw: " https://images.example.com/a.png ", want: "https://images.example.com/a.png", valid: true},
+		{name: "javascript", raw: "javascript:alert(1)"},
+		{name: "data", raw: "data:image/png;base64,AAAA"},
+		{name: "relative", raw: "/avatars/a.png"},
+		{name: "ftp", raw: "ftp://files.example.com/a.png"},
+		{name: "too long", raw: "https://images.example.com/" + strings.Repeat("a", maxProfileImageURLLength)},
+		{name: "restricted host", raw: "https://images.example.com/a.png", cfg: restricted},
+		{name: "restricted prefix", raw: "https://cdn.example.com/mediax/a.png", cfg: restricted},
+		{name: "restricted match", raw: "https://cdn.example.com/media/profiles/u/a.png", cfg: restricted, want: "https://cdn.example.com/media/profiles/u/a.png", valid: true},
+	}
+	for _, tc := range cases {
+		got, err := normalizeProfileImageURL("avatar URL", tc.raw, tc.cfg)
+		if tc.valid && (err != nil || got != tc.want) {
+			t.Fatalf("%s: expected %q, got %q (%v)", tc.name, tc.want, got, err)
+		}
+		if !tc.valid && err == nil {
+			t.Fatalf("%s: expected %q to be rejected", tc.name, tc.raw)
+		}
+	}
+}
+
+func TestInspectProfileImage(t *testing.T) {
+	if contentType, ext, err := inspectProfileImage(ProfileImageAvatar, testJPEG(t, 256, 256)); err != nil || contentType != "image/jpeg" || ext != "jpg" {
+		t.Fatalf("expected jpeg avatar accepted, got %q %q %v", contentType, ext, err)
+	}
+	if contentType, ext, err := inspectProfileImage(ProfileImageBanner, testWebP(1500, 500)); err != nil || contentType != "image/webp" || ext != "webp" {
+		t.Fatalf("expected webp banner accepted, got %q %q %v", contentType, ext, err)
+	}
+	for name, tc := range map[string]struct {
+		kind ProfileImageKind
+		data []byte
+	}{
+		"not an image":      {ProfileImageAvatar, []byte("<svg xmlns='http://www.w3.org/2000/svg'/>")},
+		"too small":         {ProfileImageAvatar, testJPEG(t, 16, 16)},
+		"too wide":          {ProfileImageAvatar, testWebP(4000, 100)},
+		"banner too narrow": {ProfileImageBanner, testJPEG(t, 100, 100)},
+		"too many bytes":    {ProfileImageAvatar, append(testWebP(64, 64), make([]byte, 2<<20)...)},
+	} {
+		if _, _, err := inspectProfileImage(tc.kind, tc.data); !errors.Is(err, ErrInvalidProfileImage) {
+			t.Fatalf("%s: expected ErrInvalidProfileImage, got %v", name, err)
+		}
+	}
+}
+
+func TestSetProfileImageReplacesPreviousObject(t *testing.T) {
+	store := newTestStoreWithController(t, nil, WithObjectStorage(ObjectStorageConfig{
+		Bucket:         "media",
+		Prefix:         "site",
+		PublicEndpoint: "https://cdn.example.com",
+	}))
+	fakeStorage := &fakeObjectStorage{prefix: "site", baseURL: "https://cdn.example.com"}
+	store.objectClient = fakeStorage
+
+	user, err := store.CreateUser(CreateUserParams{DisplayName: "Ava", Email: "ava@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	first, err := store.SetProfileImage(user.ID, ProfileImageAvatar, testJPEG(t, 128, 128))
+	if err != nil {
+		t.Fatalf("SetProfileImage: %v", err)
+	}
+	if len(fakeStorage.uploads) != 1 {
+		t.Fatalf("expected one upload, got %d", len(fakeStorage.uploads))
+	}
+	uploaded := fakeStorage.uploads[0]
+	if !strings.HasPrefix(uploaded.Key, "site/profiles/"+user.ID+"/avatar-") || uploaded.ContentType != "image/jpeg" {
+		t.Fatalf("unexpected upload %s (%s)", uploaded.Key, uploaded.ContentType)
+	}
+	if first.AvatarURL != uploaded.URL {
+		t.Fatalf("expected avatar URL %s, got %s", uploaded.URL, first.AvatarURL)
+	}
+
+	if _, err := store.SetProfileImage(user.ID, ProfileImageAvatar, testJPEG(t, 256, 256)); err != nil {
+		t.Fatalf("SetProfileImage replacement: %v", err)
+	}
+	if len(fakeStorage.deletes) != 1 || fakeStorage.deletes[0] != uploaded.Key {
+		t.Fatalf("expected %s deleted on replacement, got %v", uploaded.Key, fakeStorage.deletes)
+	}
+
+	external := "https://images.example.com/me.png"
+	if _, err := store.UpsertProfile(user.ID, ProfileUpdate{AvatarURL: &external}); err != nil {
+		t.Fatalf("UpsertProfile: %v", err)
+	}
+	if len(fakeStorage.deletes) != 2 || fakeStorage.deletes[1] != fakeStorage.uploads[1].Key {
+		t.Fatalf("expected the uploaded avatar deleted when replaced by a URL, got %v", fakeStorage.deletes)
+	}
+	bio := "hello"
+	if _, err := store.UpsertProfile(user.ID, ProfileUpdate{Bio: &bio}); err != nil {
+		t.Fatalf("UpsertProfile: %v", err)
+	}
+	if len(fakeStorage.deletes) != 2 {
+		t.Fatalf("expected URLs set by hand never to be deleted, got %v", fakeStorage.deletes)
+	}
+
+	if _, err := store.SetProfileImage(user.ID, ProfileImageBanner, []byte("not an image")); !errors.Is(err, ErrInvalidProfileImage) {
+		t.Fatalf("expected ErrInvalidProfileImage, got %v", err)
+	}
+	if len(fakeStorage.uploads) != 2 {
+		t.Fatalf("expected rejected images not to be uploaded, got %d uploads", len(fakeStorage.uploads))
+	}
+}
+
+func TestSetProfileImageWithoutObjectStorage(t *testing.T) {
+	store := newTestStore(t)
+	user, err := store.CreateUser(CreateUserParams{DisplayName: "Ava", Email: "ava@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	if _, err := store.SetProfileImage(user.ID, ProfileImageAvatar, testJPEG(t, 128, 128)); !errors.Is(err, ErrObjectStorageUnavailable) {
+		t.Fatalf("expected ErrObjectStorageUnavailable, got %v", err)
+	}
+}
@@ -0,0 +1,239 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+	"time"
+)
+
+// Recommendation reasons name the signal that put a channel in a viewer's
+// recommendations.
+const (
+	// RecommendationReasonFollowOverlap means viewers who follow the same
+	// channels as the user also follow the channel.
+	RecommendationReasonFollowOverlap = "follow_overlap"
+	// RecommendationReasonCategory means the channel streams in a category
+	// the user's followed channels use.
+	RecommendationReasonCategory = "category"
+	// RecommendationReasonPopular is the cold-start fallback: a live channel
+	// with many followers, for users who follow nothing to learn from.
+	RecommendationReasonPopular = "popular"
+)
+
+const (
+	// DefaultRecommendationLimit is how many recommendations
+	// RecommendChannels returns when no limit is given.
+	DefaultRecommendationLimit = 20
+	// MaxRecommendationLimit caps the limit RecommendChannels accepts.
+	MaxRecommendationLimit = 50
+)
+
+// Recommendation weights. A channel scores recommendationOverlapWeight for
+// every viewer who shares a follow with the user and follows it too,
+// recommendationCategoryWeight for every followed channel in its category,
+// and recommendationLiveBoost while it is live.
+const (
+	recommendationOverlapWeight  = 2
+	recommendationCategoryWeight = 1
+	recommendationLiveBoost      = 1
+)
+
+// ChannelRecommendation is one channel recommended to a user. Reason is one
+// of the RecommendationReason values. ViaChannelID names the followed
+// channel whose followers most often follow this one, for follow-overlap
+// recommendations, and Category the channel's category.
+type ChannelRecommendation struct {
+	ChannelID    string
+	Reason       string
+	ViaChannelID string
+	Category     string
+}
+
+// recommendationCandidate accumulates the signals for one channel.
+type recommendationCandidate struct {
+	channelID    string
+	peers        int
+	viaChannelID string
+	affinity     int
+	live         bool
+	followers    int
+	createdAt    time.Time
+}
+
+func (c recommendationCandidate) score() int {
+	score := c.peers*recommendationOverlapWeight + c.affinity*recommendationCategoryWeight
+	if c.live {
+		score += recommendationLiveBoost
+	}
+	return score
+}
+
+func (c recommendationCandidate) recommendation(category string) ChannelRecommendation {
+	if c.peers > 0 {
+		return ChannelRecommendation{ChannelID: c.channelID, Reason: RecommendationReasonFollowOverlap, ViaChannelID: c.viaChannelID, Category: category}
+	}
+	return ChannelRecommendation{ChannelID: c.channelID, Reason: RecommendationReasonCategory, Category: category}
+}
+
+func normalizeRecommendationLimit(limit int) int {
+	if limit <= 0 {
+		return DefaultRecommendationLimit
+	}
+	if limit > MaxRecommendationLimit {
+		return MaxRecommendationLimit
+	}
+	return limit
+}
+
+func isLiveState(state string) bool {
+	return state == "live" || state == "starting"
+}
+
+// RecommendChannels ranks channels the user does not follow or own. Channels
+// followed by viewers who share follows with the user count most, then
+// channels in the categories the user follows, with a boost while live; ties
+// go to the channel with more followers, then the older channel. Users with
+// no such signal get the most-followed live channels instead. Channels of
+// deactivated owners are never recommended.
+func (s *Storage) RecommendChannels(userID string, limit int) ([]ChannelRecommendation, error) {
+	userID = strings.TrimSpace(userID)
+	limit = normalizeRecommendationLimit(limit)
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	mine := s.data.Follows[userID]
+	followers := make(map[string]int, len(s.data.Channels))
+	candidates := make(map[string]*recommendationCandidate)
+	via := make(map[string]map[string]int)
+	for peerID, theirs := range s.data.Follows {
+		for channelID := range theirs {
+			followers[channelID]++
+		}
+		if peerID == userID {
+			continue
+		}
+		shared := make([]string, 0)
+		for channelID := range theirs {
+			if _, ok := mine[channelID]; ok {
+				shared = append(shared, channelID)
+			}
+		}
+		if len(shared) == 0 {
+			continue
+		}
+		for channelID := range theirs {
+			if _, ok := mine[channelID]; ok {
+				continue
+			}
+			candidate, ok := candidates[channelID]
+			if !ok {
+				candidate = &recommendationCandidate{channelID: channelID}
+				candidates[channelID] = candidate
+				via[channelID] = make(map[string]int)
+			}
+			candidate.peers++
+			for _, sharedID := range shared {
+				via[channelID][sharedID]++
+			}
+		}
+	}
+
+	affinity := make(map[string]int)
+	for channelID := range mine {
+		if channel, ok := s.data.Channels[channelID]; ok && channel.Category != "" {
+			affinity[channel.Category]++
+		}
+	}
+
+	ranked := make([]recommendationCandidate, 0)
+	for id, channel := range s.data.Channels {
+		if _, followed := mine[id]; followed || channel.OwnerID == userID || !s.recommendableOwnerLocked(channel.OwnerID) {
+			continue
+		}
+		candidate := recommendationCandidate{channelID: id}
+		if found, ok := candidates[id]; ok {
+			candidate = *found
+			candidate.viaChannelID = topRecommendationVia(via[id])
+		}
+		if channel.Category != "" {
+			candidate.affinity = affinity[channel.Category]
+		}
+		if candidate.peers == 0 && candidate.affinity == 0 {
+			continue
+		}
+		candidate.live = isLiveState(channel.LiveState)
+		candidate.followers = followers[id]
+		candidate.createdAt = channel.CreatedAt
+		ranked = append(ranked, candidate)
+	}
+
+	if len(ranked) > 0 {
+		sort.Slice(ranked, func(i, j int) bool {
+			if si, sj := ranked[i].score(), ranked[j].score(); si != sj {
+				return si > sj
+			}
+			return popularBefore(ranked[i], ranked[j])
+		})
+		if len(ranked) > limit {
+			ranked = ranked[:limit]
+		}
+		recommendations := make([]ChannelRecommendation, 0, len(ranked))
+		for _, candidate := range ranked {
+			recommendations = append(recommendations, candidate.recommendation(s.data.Channels[candidate.channelID].Category))
+		}
+		return recommendations, nil
+	}
+
+	popular := make([]recommendationCandidate, 0)
+	for id, channel := range s.data.Channels {
+		if !isLiveState(channel.LiveState) {
+			continue
+		}
+		if _, followed := mine[id]; followed || channel.OwnerID == userID || !s.recommendableOwnerLocked(channel.OwnerID) {
+			continue
+		}
+		popular = append(popular, recommendationCandidate{channelID: id, followers: followers[id], createdAt: channel.CreatedAt})
+	}
+	sort.Slice(popular, func(i, j int) bool {
+		return popularBefore(popular[i], popular[j])
+	})
+	if len(popular) > limit {
+		popular = popular[:limit]
+	}
+	recommendations := make([]ChannelRecommendation, 0, len(popular))
+	for _, candidate := range popular {
+		recommendations = append(recommendations, ChannelRecommendation{ChannelID: candidate.channelID, Reason: RecommendationReasonPopular, Category: s.data.Channels[candidate.channelID].Category})
+	}
+	return recommendations, nil
+}
+
+// recommendableOwnerLocked reports whether the owner exists and is active.
+// Callers must hold s.mu.
+func (s *Storage) recommendableOwnerLocked(ownerID string) bool {
+	owner, ok := s.data.Users[ownerID]
+	return ok && owner.DeactivatedAt == nil
+}
+
+// popularBefore orders channels by follower count, then age, then ID.
+func popularBefore(a, b recommendationCandidate) bool {
+	if a.followers != b.followers {
+		return a.followers > b.followers
+	}
+	if !a.createdAt.Equal(b.createdAt) {
+		return a.createdAt.Before(b.createdAt)
+	}
+	return a.channelID < b.channelID
+}
+
+// topRecommendationVia picks the followed channel sharing the most viewers,
+// breaking ties by ID.
+func topRecommendationVia(counts map[string]int) string {
+	best, bestCount := "", 0
+	for channelID, count := range counts {
+		if count > bestCount || (count == bestCount && channelID < best) {
+			best, bestCount = channelID, count
+		}
+	}
+	return best
+}
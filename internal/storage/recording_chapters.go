@@ -0,0 +1,119 @@
+package storage
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"bitriver-live/internal/models"
+)
+
+const (
+	// MaxRecordingChapters caps how many chapters one recording can hold.
+	MaxRecordingChapters = 50
+	// MaxRecordingChapterTitleLength is the longest chapter title, in
+	// characters. It matches the marker label limit so every marker fits.
+	MaxRecordingChapterTitleLength = MaxStreamMarkerLabelLength
+)
+
+// normalizeRecordingChapters validates a full chapter list for a recording
+// lasting duration seconds and returns it ordered by offset. Offsets must
+// fall inside the recording and be distinct. It returns nil when the list is
+// empty.
+func normalizeRecordingChapters(chapters []models.RecordingChapter, duration int) ([]models.RecordingChapter, error) {
+	if len(chapters) > MaxRecordingChapters {
+		return nil, invalid("chapters", "recordings can have at most %d chapters", MaxRecordingChapters)
+	}
+	if len(chapters) == 0 {
+		return nil, nil
+	}
+	normalized := make([]models.RecordingChapter, 0, len(chapters))
+	seen := make(map[int]struct{}, len(chapters))
+	for _, chapter := range chapters {
+		title := strings.TrimSpace(chapter.Title)
+		if title == "" {
+			return nil, invalid("chapters", "chapter title is required")
+		}
+		if utf8.RuneCountInString(title) > MaxRecordingChapterTitleLength {
+			return nil, invalid("chapters", "chapter titles must be %d characters or fewer", MaxRecordingChapterTitleLength)
+		}
+		if strings.IndexFunc(title, unicode.IsControl) >= 0 {
+			return nil, invalid("chapters", "chapter titles cannot contain control characters")
+		}
+		if chapter.OffsetSeconds < 0 {
+			return nil, invalid("chapters", "chapter offsets cannot be negative")
+		}
+		if duration > 0 && chapter.OffsetSeconds >= duration {
+			return nil, invalid("chapters", "chapter offset %d is past the end of the recording", chapter.OffsetSeconds)
+		}
+		if _, ok := seen[chapter.OffsetSeconds]; ok {
+			return nil, invalid("chapters", "more than one chapter starts at %d seconds", chapter.OffsetSeconds)
+		}
+		seen[chapter.OffsetSeconds] = struct{}{}
+		normalized = append(normalized, models.RecordingChapter{OffsetSeconds: chapter.OffsetSeconds, Title: title})
+	}
+	sort.Slice(normalized, func(i, j int) bool {
+		return normalized[i].OffsetSeconds < normalized[j].OffsetSeconds
+	})
+	return normalized, nil
+}
+
+// chaptersFromMarkers turns a session's markers, ordered by offset, into the
+// starting chapters of its recording. Markers past the end of the recording
+// are dropped, and when several share an offset the earliest one wins.
+func chaptersFromMarkers(markers []models.StreamMarker, duration int) []models.RecordingChapter {
+	var chapters []models.RecordingChapter
+	for _, marker := range markers {
+		if len(chapters) == MaxRecordingChapters {
+			break
+		}
+		if duration > 0 && marker.OffsetSeconds >= duration {
+			continue
+		}
+		if n := len(chapters); n > 0 && chapters[n-1].OffsetSeconds == marker.OffsetSeconds {
+			continue
+		}
+		chapters = append(chapters, models.RecordingChapter{OffsetSeconds: marker.OffsetSeconds, Title: marker.Label})
+	}
+	return chapters
+}
+
+// sessionMarkersLocked returns the markers of a session ordered by offset.
+// Callers must hold s.mu.
+func (s *Storage) sessionMarkersLocked(sessionID string) []models.StreamMarker {
+	var markers []models.StreamMarker
+	for _, marker := range s.data.StreamMarkers {
+		if marker.SessionID == sessionID {
+			markers = append(markers, marker)
+		}
+	}
+	sortStreamMarkers(markers)
+	return markers
+}
+
+// SetRecordingChapters replaces a recording's chapters. An empty list removes
+// them all.
+func (s *Storage) SetRecordingChapters(id string, chapters []models.RecordingChapter) (models.Recording, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	recording, ok := s.data.Recordings[id]
+	if !ok {
+		return models.Recording{}, notFound("recording", id)
+	}
+	normalized, err := normalizeRecordingChapters(chapters, recording.DurationSeconds)
+	if err != nil {
+		return models.Recording{}, err
+	}
+	updated := cloneRecording(recording)
+	updated.Chapters = normalized
+
+	snapshot := cloneDataset(s.data)
+	s.data.Recordings[id] = updated
+	if err := s.persist(); err != nil {
+		s.data = snapshot
+		return models.Recording{}, err
+	}
+	return s.recordingWithClipsLocked(updated), nil
+}
@@ -0,0 +1,339 @@
+package storage
+
+import (
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	"bitriver-live/internal/models"
+)
+
+// Limits on the metadata creators can set on a recording, in characters.
+const (
+	MaxRecordingTitleLength       = 140
+	MaxRecordingDescriptionLength = 5000
+	MaxRecordingTags              = 10
+	MaxRecordingTagLength         = 32
+)
+
+// Recording batch result statuses.
+const (
+	RecordingBatchUpdated = "updated"
+	RecordingBatchSkipped = "skipped"
+	RecordingBatchFailed  = "error"
+)
+
+// RecordingUpdate is a partial edit of one recording's metadata. Nil fields
+// are left unchanged.
+type RecordingUpdate struct {
+	Title       *string
+	Description *string
+	Tags        *[]string
+	// PremiereAt schedules the recording to premiere at a future time; the
+	// zero time cancels a scheduled premiere. Only unpublished recordings
+	// can be scheduled.
+	PremiereAt *time.Time
+}
+
+// RecordingBatchUpdate is a metadata edit applied to many recordings at once.
+// Tags replaces each recording's tags, while AddTags and RemoveTags edit the
+// existing ones; the two styles cannot be combined.
+type RecordingBatchUpdate struct {
+	Description *string
+	Tags        *[]string
+	AddTags     []string
+	RemoveTags  []string
+}
+
+// RecordingBatchResult reports what a batch update did to one recording.
+// Recordings the update would not change are skipped, and recordings that
+// are unknown, belong to another channel, or would end up with too many tags
+// fail without affecting the rest of the batch.
+type RecordingBatchResult struct {
+	RecordingID string
+	Status      string
+	Error       string
+}
+
+// normalizeRecordingTags lowercases, trims, dedupes, and sorts tags like
+// channel tags, then enforces the recording tag limits. It returns nil when
+// no tags remain.
+func normalizeRecordingTags(tags []string) ([]string, error) {
+	normalized := normalizeTags(tags)
+	for _, tag := range normalized {
+		if utf8.RuneCountInString(tag) > MaxRecordingTagLength {
+			return nil, invalid("tags", "tags must be at most %d characters", MaxRecordingTagLength)
+		}
+		if strings.IndexFunc(tag, unicode.IsControl) >= 0 {
+			return nil, invalid("tags", "tags cannot contain control characters")
+		}
+	}
+	if len(normalized) > MaxRecordingTags {
+		return nil, invalid("tags", "recordings can have at most %d tags", MaxRecordingTags)
+	}
+	if len(normalized) == 0 {
+		return nil, nil
+	}
+	return normalized, nil
+}
+
+// normalizeRecordingDescription trims a description and enforces its length.
+// Line breaks are kept; other control characters are rejected.
+func normalizeRecordingDescription(value string) (string, error) {
+	description := strings.TrimSpace(value)
+	if utf8.RuneCountInString(description) > MaxRecordingDescriptionLength {
+		return "", invalid("description", "description must be at most %d characters", MaxRecordingDescriptionLength)
+	}
+	if strings.IndexFunc(description, func(r rune) bool { return unicode.IsControl(r) && r != '\n' && r != '\r' && r != '\t' }) >= 0 {
+		return "", invalid("description", "description cannot contain control characters")
+	}
+	return description, nil
+}
+
+// normalized validates the update and normalizes its fields.
+func (u RecordingUpdate) normalized() (RecordingUpdate, error) {
+	var normalized RecordingUpdate
+	if u.Title != nil {
+		title := strings.TrimSpace(*u.Title)
+		if title == "" {
+			return RecordingUpdate{}, invalid("title", "title cannot be empty")
+		}
+		if utf8.RuneCountInString(title) > MaxRecordingTitleLength {
+			return RecordingUpdate{}, invalid("title", "title must be at most %d characters", MaxRecordingTitleLength)
+		}
+		if strings.IndexFunc(title, unicode.IsControl) >= 0 {
+			return RecordingUpdate{}, invalid("title", "title cannot contain control characters")
+		}
+		normalized.Title = &title
+	}
+	if u.Description != nil {
+		description, err := normalizeRecordingDescription(*u.Description)
+		if err != nil {
+			return RecordingUpdate{}, err
+		}
+		normalized.Description = &description
+	}
+	if u.Tags != nil {
+		tags, err := normalizeRecordingTags(*u.Tags)
+		if err != nil {
+			return RecordingUpdate{}, err
+		}
+		normalized.Tags = &tags
+	}
+	if u.PremiereAt != nil {
+		premiereAt := u.PremiereAt.UTC().Truncate(time.Second)
+		if !premiereAt.IsZero() && !premiereAt.After(time.Now()) {
+			return RecordingUpdate{}, invalid("premiereAt", "premiereAt must be in the future")
+		}
+		normalized.PremiereAt = &premiereAt
+	}
+	if normalized.Title == nil && normalized.Description == nil && normalized.Tags == nil && normalized.PremiereAt == nil {
+		return RecordingUpdate{}, invalid("", "update requires a title, description, tags, or premiereAt")
+	}
+	return normalized, nil
+}
+
+// apply copies the update onto recording. The update must already be
+// normalized.
+func (u RecordingUpdate) apply(recording *models.Recording) {
+	if u.Title != nil {
+		recording.Title = *u.Title
+	}
+	if u.Description != nil {
+		recording.Description = *u.Description
+	}
+	if u.Tags != nil {
+		recording.Tags = append([]string(nil), (*u.Tags)...)
+	}
+	if u.PremiereAt != nil {
+		recording.PremiereAt = nil
+		if !u.PremiereAt.IsZero() {
+			premiereAt := *u.PremiereAt
+			recording.PremiereAt = &premiereAt
+		}
+	}
+}
+
+// checkPremiere reports why the update cannot change the premiere of
+// recording, or nil when it may.
+func (u RecordingUpdate) checkPremiere(recording models.Recording) error {
+	if u.PremiereAt == nil {
+		return nil
+	}
+	if recording.PublishedAt != nil {
+		return ErrRecordingPublished
+	}
+	if recording.OverQuota && !u.PremiereAt.IsZero() {
+		return precondition("recording is over its channel's storage quota")
+	}
+	return nil
+}
+
+// normalized validates the batch update and normalizes its fields.
+func (u RecordingBatchUpdate) normalized() (RecordingBatchUpdate, error) {
+	var normalized RecordingBatchUpdate
+	if u.Description != nil {
+		description, err := normalizeRecordingDescription(*u.Description)
+		if err != nil {
+			return RecordingBatchUpdate{}, err
+		}
+		normalized.Description = &description
+	}
+	if u.Tags != nil {
+		if len(u.AddTags) > 0 || len(u.RemoveTags) > 0 {
+			return RecordingBatchUpdate{}, invalid("tags", "tags cannot be combined with addTags or removeTags")
+		}
+		tags, err := normalizeRecordingTags(*u.Tags)
+		if err != nil {
+			return RecordingBatchUpdate{}, err
+		}
+		normalized.Tags = &tags
+	}
+	var err error
+	if normalized.AddTags, err = normalizeRecordingTags(u.AddTags); err != nil {
+		return RecordingBatchUpdate{}, err
+	}
+	if removed := normalizeTags(u.RemoveTags); len(removed) > 0 {
+		normalized.RemoveTags = removed
+	}
+	if normalized.Description == nil && normalized.Tags == nil && len(normalized.AddTags) == 0 && len(normalized.RemoveTags) == 0 {
+		return RecordingBatchUpdate{}, invalid("", "batch update requires a description or tag change")
+	}
+	return normalized, nil
+}
+
+// apply returns recording with the update applied and whether anything
+// changed. It fails when the added tags would exceed MaxRecordingTags. The
+// update must already be normalized.
+func (u RecordingBatchUpdate) apply(recording models.Recording) (models.Recording, bool, error) {
+	next := cloneRecording(recording)
+	if u.Description != nil {
+		next.Description = *u.Description
+	}
+	if u.Tags != nil {
+		next.Tags = append([]string(nil), (*u.Tags)...)
+	} else if len(u.AddTags) > 0 || len(u.RemoveTags) > 0 {
+		edit := ChannelBatchUpdate{AddTags: u.AddTags, RemoveTags: u.RemoveTags}
+		_, tags, _ := edit.apply("", recording.Tags)
+		if len(tags) > MaxRecordingTags {
+			return recording, false, invalid("tags", "recordings can have at most %d tags", MaxRecordingTags)
+		}
+		next.Tags = nil
+		if len(tags) > 0 {
+			next.Tags = tags
+		}
+	}
+	return next, next.Description != recording.Description || !equalTags(next.Tags, recording.Tags), nil
+}
+
+func equalTags(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}
+
+// RecordingHasTags reports whether recording carries every one of tags,
+// compared after normalization. Every recording matches an empty list.
+func RecordingHasTags(recording models.Recording, tags []string) bool {
+	for _, want := range normalizeTags(tags) {
+		found := false
+		for _, tag := range recording.Tags {
+			if tag == want {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return false
+		}
+	}
+	return true
+}
+
+// UpdateRecording edits the title, description, and tags of a recording
+// and schedules or cancels its premiere.
+func (s *Storage) UpdateRecording(id string, update RecordingUpdate) (models.Recording, error) {
+	normalized, err := update.normalized()
+	if err != nil {
+		return models.Recording{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	recording, ok := s.data.Recordings[id]
+	if !ok {
+		return models.Recording{}, notFound("recording", id)
+	}
+	if err := normalized.checkPremiere(recording); err != nil {
+		return models.Recording{}, err
+	}
+	updated := cloneRecording(recording)
+	normalized.apply(&updated)
+	if updated.PremiereAt != nil && updated.RetainUntil != nil {
+		// Keep the unpublished recording until its premiere has had a full
+		// unpublished window of its own.
+		if deadline := s.recordingDeadline(*updated.PremiereAt, false); deadline != nil && updated.RetainUntil.Before(*deadline) {
+			updated.RetainUntil = deadline
+		}
+	}
+
+	snapshot := cloneDataset(s.data)
+	s.data.Recordings[id] = updated
+	if err := s.persist(); err != nil {
+		s.data = snapshot
+		return models.Recording{}, err
+	}
+	return s.recordingWithClipsLocked(updated), nil
+}
+
+// BatchUpdateRecordings applies update to every listed recording of the
+// channel and persists all changes together.
+func (s *Storage) BatchUpdateRecordings(channelID string, ids []string, update RecordingBatchUpdate) ([]RecordingBatchResult, error) {
+	normalized, err := update.normalized()
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	updatedData := cloneDataset(s.data)
+	results := make([]RecordingBatchResult, 0, len(ids))
+	modified := false
+	for _, id := range uniqueIDs(ids) {
+		recording, ok := updatedData.Recordings[id]
+		if !ok || recording.ChannelID != channelID {
+			results = append(results, RecordingBatchResult{RecordingID: id, Status: RecordingBatchFailed, Error: fmt.Sprintf("recording %s not found", id)})
+			continue
+		}
+		next, changed, err := normalized.apply(recording)
+		if err != nil {
+			results = append(results, RecordingBatchResult{RecordingID: id, Status: RecordingBatchFailed, Error: err.Error()})
+			continue
+		}
+		if !changed {
+			results = append(results, RecordingBatchResult{RecordingID: id, Status: RecordingBatchSkipped})
+			continue
+		}
+		updatedData.Recordings[id] = next
+		modified = true
+		results = append(results, RecordingBatchResult{RecordingID: id, Status: RecordingBatchUpdated})
+	}
+
+	if modified {
+		if err := s.persistDataset(updatedData); err != nil {
+			return nil, err
+		}
+		s.data = updatedData
+	}
+	return results, nil
+}
@@ -0,0 +1,196 @@
+package storage
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"net/url"
+	"path"
+	"regexp"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/models"
+)
+
+// ErrRecordingPlaybackUnavailable is returned by RecordingPlayback when the
+// recording's playlists are not archived in object storage or the bucket
+// cannot presign URLs. Callers fall back to the stored manifest URLs.
+var ErrRecordingPlaybackUnavailable = errors.New("recording playback cannot be presigned")
+
+// masterPlaylistName is the name the generated master playlist is resolved
+// as, inside the recording's object directory.
+const masterPlaylistName = "master.m3u8"
+
+// RecordingPlayback holds short-lived presigned URLs for a recording kept in
+// a private bucket.
+type RecordingPlayback struct {
+	RecordingID string
+	// MasterPlaylist is an HLS master playlist whose variant URIs are the
+	// presigned rendition URLs.
+	MasterPlaylist string
+	// Renditions carry presigned ManifestURLs in the recording's order.
+	Renditions []models.RecordingRendition
+	ExpiresAt  time.Time
+}
+
+// archivedPlaylistExtension is the extension of a rendition manifest archived
+// as an HLS media playlist. StopStream archives each rendition as JSON
+// metadata about the live manifest instead, which players cannot open, so
+// those recordings have no presigned playback until their playlists are
+// archived.
+const archivedPlaylistExtension = ".m3u8"
+
+// presignRecordingPlayback presigns every rendition playlist the recording
+// archived to object storage. The master playlist is written with URIs
+// relative to the recording's directory and then rewritten, so each variant
+// is presigned the same way as a stored playlist would be.
+func presignRecordingPlayback(client objectStorageClient, recording models.Recording, ttl time.Duration, now time.Time) (RecordingPlayback, error) {
+	if client == nil || !client.Enabled() {
+		return RecordingPlayback{}, ErrRecordingPlaybackUnavailable
+	}
+	if ttl > maxObjectStoragePresignTTL {
+		ttl = maxObjectStoragePresignTTL
+	}
+	keys := make([]string, len(recording.Renditions))
+	root := ""
+	for i, rendition := range recording.Renditions {
+		key := strings.TrimSpace(recording.Metadata[manifestMetadataKey(rendition.Name)])
+		if key == "" || !strings.EqualFold(path.Ext(key), archivedPlaylistExtension) {
+			return RecordingPlayback{}, ErrRecordingPlaybackUnavailable
+		}
+		keys[i] = key
+		// Playlists live at {recording}/manifests/{name}.m3u8.
+		if dir := path.Dir(path.Dir(key)); root == "" {
+			root = dir
+		} else if dir != root {
+			return RecordingPlayback{}, fmt.Errorf("recording %s manifests are not in one directory", recording.ID)
+		}
+	}
+	if len(keys) == 0 {
+		return RecordingPlayback{}, ErrRecordingPlaybackUnavailable
+	}
+
+	signed := make(map[string]string, len(keys))
+	sign := func(key string) (string, error) {
+		if existing, ok := signed[key]; ok {
+			return existing, nil
+		}
+		presigned, err := client.PresignGet(key, ttl)
+		if errors.Is(err, ErrPresignUnavailable) {
+			return "", ErrRecordingPlaybackUnavailable
+		}
+		if err != nil {
+			return "", fmt.Errorf("presign %s: %w", key, err)
+		}
+		signed[key] = presigned
+		return presigned, nil
+	}
+
+	var master strings.Builder
+	master.WriteString("#EXTM3U\n#EXT-X-VERSION:3\n")
+	for i, rendition := range recording.Renditions {
+		master.WriteString("#EXT-X-STREAM-INF:")
+		if rendition.Bitrate > 0 {
+			fmt.Fprintf(&master, "BANDWIDTH=%d,", rendition.Bitrate*1000)
+		}
+		fmt.Fprintf(&master, "NAME=%q\n%s\n", rendition.Name, strings.TrimPrefix(keys[i], root+"/"))
+	}
+	rewritten, err := rewritePlaylist(master.String(), path.Join(root, masterPlaylistName), root, sign)
+	if err != nil {
+		return RecordingPlayback{}, err
+	}
+
+	playback := RecordingPlayback{
+		RecordingID:    recording.ID,
+		MasterPlaylist: rewritten,
+		Renditions:     make([]models.RecordingRendition, len(recording.Renditions)),
+		ExpiresAt:      now.Add(ttl),
+	}
+	for i, rendition := range recording.Renditions {
+		rendition.ManifestURL, err = sign(keys[i])
+		if err != nil {
+			return RecordingPlayback{}, err
+		}
+		playback.Renditions[i] = rendition
+	}
+	return playback, nil
+}
+
+// playlistURIAttribute matches the URI="..." attribute that EXT-X-MEDIA,
+// EXT-X-I-FRAME-STREAM-INF, EXT-X-MAP, EXT-X-KEY, and similar tags use to
+// reference other files.
+var playlistURIAttribute = regexp.MustCompile(`URI="([^"]*)"`)
+
+// rewritePlaylist replaces every relative URI in an HLS playlist, master or
+// media, with sign applied to the object key it resolves to. URIs resolve
+// against the directory of playlistKey, as a player would resolve them, and
+// must stay under root so a playlist cannot reach other objects in the
+// bucket. Absolute URLs are left alone.
+func rewritePlaylist(playlist, playlistKey, root string, sign func(key string) (string, error)) (string, error) {
+	base := path.Dir(playlistKey)
+	resolve := func(uri string) (string, error) {
+		if uri == "" {
+			return uri, nil
+		}
+		parsed, err := url.Parse(uri)
+		if err != nil {
+			return "", fmt.Errorf("parse playlist uri %q: %w", uri, err)
+		}
+		if parsed.IsAbs() || parsed.Host != "" {
+			return uri, nil
+		}
+		key := path.Clean(path.Join(base, parsed.Path))
+		if strings.HasPrefix(parsed.Path, "/") || (key != root && !strings.HasPrefix(key, root+"/")) {
+			return "", fmt.Errorf("playlist uri %q leaves %s", uri, root)
+		}
+		return sign(key)
+	}
+
+	var out strings.Builder
+	scanner := bufio.NewScanner(strings.NewReader(playlist))
+	scanner.Buffer(make([]byte, 64*1024), 1<<20)
+	for scanner.Scan() {
+		line := strings.TrimRight(scanner.Text(), "\r")
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case trimmed == "":
+		case strings.HasPrefix(trimmed, "#"):
+			var rewriteErr error
+			line = playlistURIAttribute.ReplaceAllStringFunc(line, func(match string) string {
+				uri := playlistURIAttribute.FindStringSubmatch(match)[1]
+				resolved, err := resolve(uri)
+				if err != nil {
+					rewriteErr = err
+					return match
+				}
+				return `URI="` + resolved + `"`
+			})
+			if rewriteErr != nil {
+				return "", rewriteErr
+			}
+		default:
+			resolved, err := resolve(trimmed)
+			if err != nil {
+				return "", err
+			}
+			line = resolved
+		}
+		out.WriteString(line)
+		out.WriteByte('\n')
+	}
+	if err := scanner.Err(); err != nil {
+		return "", fmt.Errorf("read playlist: %w", err)
+	}
+	return out.String(), nil
+}
+
+// RecordingPlayback presigns the manifests of a recording archived to object
+// storage.
+func (s *Storage) RecordingPlayback(id string, ttl time.Duration) (RecordingPlayback, error) {
+	recording, ok := s.GetRecording(id)
+	if !ok {
+		return RecordingPlayback{}, notFound("recording", id)
+	}
+	return presignRecordingPlayback(s.objectClient, recording, ttl, time.Now().UTC())
+}
@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/ingest"
+	"bitriver-live/internal/models"
+)
+
+func TestPresignRecordingPlaybackSignsEveryRendition(t *testing.T) {
+	client := &fakeObjectStorage{}
+	recording := models.Recording{
+		ID: "rec-1",
+		Renditions: []models.RecordingRendition{
+			{Name: "1080p", ManifestURL: "https://cdn.example.com/1080p.m3u8", Bitrate: 6000},
+			{Name: "720p", ManifestURL: "https://cdn.example.com/720p.m3u8"},
+		},
+		Metadata: map[string]string{
+			manifestMetadataKey("1080p"): "vod/recordings/rec-1/manifests/1080p.m3u8",
+			manifestMetadataKey("720p"):  "vod/recordings/rec-1/manifests/720p.m3u8",
+		},
+	}
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	playback, err := presignRecordingPlayback(client, recording, 10*time.Minute, now)
+	if err != nil {
+		t.Fatalf("presignRecordingPlayback: %v", err)
+	}
+	if !playback.ExpiresAt.Equal(now.Add(10 * time.Minute)) {
+		t.Fatalf("expected expiry in ten minutes, got %s", playback.ExpiresAt)
+	}
+	if len(client.presigned) != 2 {
+		t.Fatalf("expected each manifest presigned once, got %v", client.presigned)
+	}
+	if got := playback.Renditions[0].ManifestURL; got != "https://signed.example.com/vod/recordings/rec-1/manifests/1080p.m3u8?expires=600" {
+		t.Fatalf("unexpected signed rendition URL %q", got)
+	}
+	if playback.Renditions[0].Bitrate != 6000 || playback.Renditions[1].Name != "720p" {
+		t.Fatalf("expected rendition details kept, got %+v", playback.Renditions)
+	}
+	expected := strings.Join([]string{
+		"#EXTM3U",
+		"#EXT-X-VERSION:3",
+		`#EXT-X-STREAM-INF:BANDWIDTH=6000000,NAME="1080p"`,
+		"https://signed.example.com/vod/recordings/rec-1/manifests/1080p.m3u8?expires=600",
+		`#EXT-X-STREAM-INF:NAME="720p"`,
+		"https://signed.example.com/vod/recordings/rec-1/manifests/720p.m3u8?expires=600",
+		"",
+	}, "\n")
+	if playback.MasterPlaylist != expected {
+		t.Fatalf("unexpected master playlist:\n%s", playback.MasterPlaylist)
+	}
+}
+
+func TestPresignRecordingPlaybackUnavailable(t *testing.T) {
+	recording := models.Recording{ID: "rec-1", Renditions: []models.RecordingRendition{{Name: "720p", ManifestURL: "https://origin/720p.m3u8"}}}
+	if _, err := presignRecordingPlayback(noopObjectStorageClient{}, recording, time.Minute, time.Now()); !errors.Is(err, ErrRecordingPlaybackUnavailable) {
+		t.Fatalf("expected ErrRecordingPlaybackUnavailable without object storage, got %v", err)
+	}
+	if _, err := presignRecordingPlayback(&fakeObjectStorage{}, recording, time.Minute, time.Now()); !errors.Is(err, ErrRecordingPlaybackUnavailable) {
+		t.Fatalf("expected ErrRecordingPlaybackUnavailable for manifests outside the bucket, got %v", err)
+	}
+	recording.Metadata = map[string]string{manifestMetadataKey("720p"): "recordings/rec-1/manifests/720p.m3u8"}
+	if _, err := presignRecordingPlayback(&hangingDeleteObjectStorage{}, recording, time.Minute, time.Now()); !errors.Is(err, ErrRecordingPlaybackUnavailable) {
+		t.Fatalf("expected ErrRecordingPlaybackUnavailable when the bucket cannot presign, got %v", err)
+	}
+	client := &fakeObjectStorage{}
+	recording.Metadata = map[string]string{manifestMetadataKey("720p"): "recordings/rec-1/manifests/720p.json"}
+	if _, err := presignRecordingPlayback(client, recording, time.Minute, time.Now()); !errors.Is(err, ErrRecordingPlaybackUnavailable) {
+		t.Fatalf("expected ErrRecordingPlaybackUnavailable for rendition metadata, got %v", err)
+	}
+	if len(client.presigned) != 0 {
+		t.Fatalf("expected nothing presigned for rendition metadata, got %v", client.presigned)
+	}
+}
+
+func TestRecordingPlaybackFromStopStreamArchive(t *testing.T) {
+	controller := &fakeIngestController{bootResponses: []bootResponse{{result: ingest.BootResult{
+		PlaybackURL: "https://playback.example.com/stream.m3u8",
+		Renditions: []ingest.Rendition{
+			{Name: "1080p", ManifestURL: "https://origin/1080p.m3u8", Bitrate: 6000},
+			{Name: "720p", ManifestURL: "https://origin/720p.m3u8", Bitrate: 3500},
+		},
+		JobIDs: []string{"job-1"},
+	}}}}
+	store := newTestStoreWithController(t, controller, WithObjectStorage(ObjectStorageConfig{Bucket: "vod", Prefix: "vod/assets"}))
+	client := &fakeObjectStorage{prefix: store.objectStorage.Prefix}
+	store.objectClient = client
+
+	owner, err := store.CreateUser(CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Live", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	if _, err := store.StartStream(channel.ID, []string{"1080p", "720p"}); err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	if _, err := store.StopStream(channel.ID, 42); err != nil {
+		t.Fatalf("StopStream: %v", err)
+	}
+	if len(client.uploads) == 0 {
+		t.Fatal("expected StopStream to archive the renditions")
+	}
+
+	// StopStream archives rendition metadata rather than playlists, so the
+	// recording has nothing a player could open through a signed URL.
+	if _, err := store.RecordingPlayback(firstRecordingID(store), time.Minute); !errors.Is(err, ErrRecordingPlaybackUnavailable) {
+		t.Fatalf("expected ErrRecordingPlaybackUnavailable for a StopStream archive, got %v", err)
+	}
+	if len(client.presigned) != 0 {
+		t.Fatalf("expected nothing presigned, got %v", client.presigned)
+	}
+}
+
+func TestRewritePlaylistResolvesNestedVariants(t *testing.T) {
+	sign := func(key string) (string, error) { return "https://signed.example.com/" + key + "?sig=1", nil }
+	master := strings.Join([]string{
+		"#EXTM3U",
+		`#EXT-X-MEDIA:TYPE=AUDIO,GROUP-ID="aud",NAME="English",URI="audio/en/index.m3u8"`,
+		`#EXT-X-STREAM-INF:BANDWIDTH=3500000,AUDIO="aud"`,
+		"720p/index.m3u8",
+		`#EXT-X-I-FRAME-STREAM-INF:BANDWIDTH=90000,URI="720p/iframes.m3u8"`,
+		"#EXT-X-STREAM-INF:BANDWIDTH=800000",
+		"https://cdn.example.com/fallback/360p.m3u8",
+		"",
+	}, "\n")
+	rewritten, err := rewritePlaylist(master, "recordings/rec-1/master.m3u8", "recordings/rec-1", sign)
+	if err != nil {
+		t.Fatalf("rewrite master: %v", err)
+	}
+	for _, want := range []string{
+		`URI="https://signed.example.com/recordings/rec-1/audio/en/index.m3u8?sig=1"`,
+		"\nhttps://signed.example.com/recordings/rec-1/720p/index.m3u8?sig=1\n",
+		`URI="https://signed.example.com/recordings/rec-1/720p/iframes.m3u8?sig=1"`,
+		"\nhttps://cdn.example.com/fallback/360p.m3u8\n",
+		`#EXT-X-STREAM-INF:BANDWIDTH=3500000,AUDIO="aud"`,
+	} {
+		if !strings.Contains(rewritten, want) {
+			t.Fatalf("expected %q in rewritten master:\n%s", want, rewritten)
+		}
+	}
+
+	media := strings.Join([]string{
+		"#EXTM3U",
+		"#EXT-X-TARGETDURATION:6",
+		`#EXT-X-MAP:URI="init.mp4"`,
+		"#EXTINF:6.0,",
+		"segment-0.m4s",
+		"#EXTINF:6.0,",
+		"../shared/segment-1.m4s",
+		"#EXT-X-ENDLIST",
+	}, "\n")
+	rewritten, err = rewritePlaylist(media, "recordings/rec-1/720p/index.m3u8", "recordings/rec-1", sign)
+	if err != nil {
+		t.Fatalf("rewrite media playlist: %v", err)
+	}
+	for _, want := range []string{
+		`#EXT-X-MAP:URI="https://signed.example.com/recordings/rec-1/720p/init.mp4?sig=1"`,
+		"\nhttps://signed.example.com/recordings/rec-1/720p/segment-0.m4s?sig=1\n",
+		"\nhttps://signed.example.com/recordings/rec-1/shared/segment-1.m4s?sig=1\n",
+		"#EXTINF:6.0,\n",
+	} {
+		if !strings.Contains(rewritten, want) {
+			t.Fatalf("expected %q in rewritten media playlist:\n%s", want, rewritten)
+		}
+	}
+
+	for _, escape := range []string{"../../rec-2/720p/index.m3u8", "/recordings/rec-1/720p/index.m3u8"} {
+		if _, err := rewritePlaylist("#EXTM3U\n"+escape+"\n", "recordings/rec-1/master.m3u8", "recordings/rec-1", sign); err == nil {
+			t.Fatalf("expected %q to be refused", escape)
+		}
+	}
+}
@@ -0,0 +1,265 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"bitriver-live/internal/models"
+)
+
+// MaxReportReasonLength is the longest free-text reason a report may carry,
+// in characters.
+const MaxReportReasonLength = 1000
+
+// ValidReportSubjectType reports whether subjectType is one of the
+// models.ReportSubject values.
+func ValidReportSubjectType(subjectType string) bool {
+	switch subjectType {
+	case models.ReportSubjectChatMessage, models.ReportSubjectStreamSession, models.ReportSubjectRecording, models.ReportSubjectChannel:
+		return true
+	}
+	return false
+}
+
+// ValidReportCategory reports whether category is one of the
+// models.ReportCategory values.
+func ValidReportCategory(category string) bool {
+	switch category {
+	case models.ReportCategoryIllegal, models.ReportCategoryExplicit, models.ReportCategoryHarassment, models.ReportCategoryCopyright, models.ReportCategoryOther:
+		return true
+	}
+	return false
+}
+
+// normalizeReportParams trims and validates params. Copyright reports must
+// name a claimant with an email address; other categories may not.
+func normalizeReportParams(params ReportParams) (ReportParams, error) {
+	params.ChannelID = strings.TrimSpace(params.ChannelID)
+	params.ReporterID = strings.TrimSpace(params.ReporterID)
+	params.SubjectType = strings.ToLower(strings.TrimSpace(params.SubjectType))
+	params.SubjectID = strings.TrimSpace(params.SubjectID)
+	params.Category = strings.ToLower(strings.TrimSpace(params.Category))
+	params.Reason = strings.TrimSpace(params.Reason)
+	params.EvidenceURL = strings.TrimSpace(params.EvidenceURL)
+
+	if params.ChannelID == "" {
+		return ReportParams{}, fmt.Errorf("channel id is required")
+	}
+	if params.ReporterID == "" {
+		return ReportParams{}, fmt.Errorf("reporter id is required")
+	}
+	if !ValidReportSubjectType(params.SubjectType) {
+		return ReportParams{}, fmt.Errorf("unknown report subject type %q", params.SubjectType)
+	}
+	if params.SubjectType == models.ReportSubjectChannel {
+		if params.SubjectID != "" && params.SubjectID != params.ChannelID {
+			return ReportParams{}, fmt.Errorf("channel report subject must be the channel")
+		}
+		params.SubjectID = params.ChannelID
+	}
+	if params.SubjectID == "" {
+		return ReportParams{}, fmt.Errorf("subject id is required")
+	}
+	if params.Category == "" {
+		return ReportParams{}, fmt.Errorf("category is required")
+	}
+	if !ValidReportCategory(params.Category) {
+		return ReportParams{}, fmt.Errorf("unknown report category %q", params.Category)
+	}
+	if params.Reason == "" {
+		return ReportParams{}, fmt.Errorf("reason is required")
+	}
+	if utf8.RuneCountInString(params.Reason) > MaxReportReasonLength {
+		return ReportParams{}, fmt.Errorf("reason must be %d characters or fewer", MaxReportReasonLength)
+	}
+
+	if params.Category != models.ReportCategoryCopyright {
+		if params.Claimant != nil {
+			return ReportParams{}, fmt.Errorf("claimant is only accepted on copyright reports")
+		}
+		return params, nil
+	}
+	if params.Claimant == nil {
+		return ReportParams{}, fmt.Errorf("claimant is required for copyright reports")
+	}
+	claimant := models.ReportClaimant{
+		Name:    strings.TrimSpace(params.Claimant.Name),
+		Email:   strings.TrimSpace(params.Claimant.Email),
+		Phone:   strings.TrimSpace(params.Claimant.Phone),
+		Address: strings.TrimSpace(params.Claimant.Address),
+	}
+	if claimant.Name == "" {
+		return ReportParams{}, fmt.Errorf("claimant name is required")
+	}
+	if !strings.Contains(claimant.Email, "@") {
+		return ReportParams{}, fmt.Errorf("claimant email is required")
+	}
+	params.Claimant = &claimant
+	return params, nil
+}
+
+// normalizeReportFilter validates the subject type and category a listing is
+// narrowed to.
+func normalizeReportFilter(filter ReportFilter) (ReportFilter, error) {
+	filter.ChannelID = strings.TrimSpace(filter.ChannelID)
+	filter.SubjectType = strings.ToLower(strings.TrimSpace(filter.SubjectType))
+	filter.Category = strings.ToLower(strings.TrimSpace(filter.Category))
+	if filter.SubjectType != "" && !ValidReportSubjectType(filter.SubjectType) {
+		return ReportFilter{}, fmt.Errorf("unknown report subject type %q", filter.SubjectType)
+	}
+	if filter.Category != "" && !ValidReportCategory(filter.Category) {
+		return ReportFilter{}, fmt.Errorf("unknown report category %q", filter.Category)
+	}
+	return filter, nil
+}
+
+func (f ReportFilter) matches(report models.ChatReport) bool {
+	if f.ChannelID != "" && report.ChannelID != f.ChannelID {
+		return false
+	}
+	if f.SubjectType != "" && report.SubjectType != f.SubjectType {
+		return false
+	}
+	if f.Category != "" && report.Category != f.Category {
+		return false
+	}
+	return f.IncludeResolved || !strings.EqualFold(report.Status, ChatReportStatusResolved)
+}
+
+// newReport builds an open report for params against targetID. A reported
+// chat message is also recorded as the report's MessageID.
+func newReport(params ReportParams, targetID string, now time.Time) (models.ChatReport, error) {
+	id, err := generateID()
+	if err != nil {
+		return models.ChatReport{}, err
+	}
+	report := models.ChatReport{
+		ID:          id,
+		ChannelID:   params.ChannelID,
+		ReporterID:  params.ReporterID,
+		TargetID:    targetID,
+		SubjectType: params.SubjectType,
+		SubjectID:   params.SubjectID,
+		Category:    params.Category,
+		Reason:      params.Reason,
+		EvidenceURL: params.EvidenceURL,
+		Claimant:    params.Claimant,
+		Status:      ChatReportStatusOpen,
+		CreatedAt:   now.UTC(),
+	}
+	if report.SubjectType == models.ReportSubjectChatMessage {
+		report.MessageID = report.SubjectID
+	}
+	return report, nil
+}
+
+// withReportDefaults fills in the subject and category of chat reports filed
+// before reports covered anything but chat messages.
+func withReportDefaults(report models.ChatReport) models.ChatReport {
+	if report.SubjectType == "" {
+		report.SubjectType = models.ReportSubjectChatMessage
+		if report.SubjectID == "" {
+			report.SubjectID = report.MessageID
+		}
+	}
+	if report.Category == "" {
+		report.Category = models.ReportCategoryOther
+	}
+	return report
+}
+
+func sortReports(reports []models.ChatReport) {
+	sort.Slice(reports, func(i, j int) bool {
+		if reports[i].CreatedAt.Equal(reports[j].CreatedAt) {
+			return reports[i].ID < reports[j].ID
+		}
+		return reports[i].CreatedAt.After(reports[j].CreatedAt)
+	})
+}
+
+// CreateReport files a report on a chat message, live session, recording, or
+// channel. The subject must belong to the channel.
+func (s *Storage) CreateReport(params ReportParams) (models.ChatReport, error) {
+	params, err := normalizeReportParams(params)
+	if err != nil {
+		return models.ChatReport{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	channel, ok := s.data.Channels[params.ChannelID]
+	if !ok {
+		return models.ChatReport{}, notFound("channel", params.ChannelID)
+	}
+	if _, ok := s.data.Users[params.ReporterID]; !ok {
+		return models.ChatReport{}, notFound("reporter", params.ReporterID)
+	}
+	targetID := channel.OwnerID
+	switch params.SubjectType {
+	case models.ReportSubjectChatMessage:
+		message, ok := s.data.ChatMessages[params.SubjectID]
+		if !ok || message.ChannelID != channel.ID {
+			return models.ChatReport{}, notFound("chat message", params.SubjectID)
+		}
+		targetID = message.UserID
+	case models.ReportSubjectStreamSession:
+		if session, ok := s.data.StreamSessions[params.SubjectID]; !ok || session.ChannelID != channel.ID {
+			return models.ChatReport{}, notFound("stream session", params.SubjectID)
+		}
+	case models.ReportSubjectRecording:
+		if recording, ok := s.data.Recordings[params.SubjectID]; !ok || recording.ChannelID != channel.ID {
+			return models.ChatReport{}, notFound("recording", params.SubjectID)
+		}
+	}
+
+	report, err := newReport(params, targetID, time.Now())
+	if err != nil {
+		return models.ChatReport{}, err
+	}
+	if s.data.ChatReports == nil {
+		s.data.ChatReports = make(map[string]models.ChatReport)
+	}
+	s.data.ChatReports[report.ID] = report
+	if err := s.persist(); err != nil {
+		delete(s.data.ChatReports, report.ID)
+		return models.ChatReport{}, err
+	}
+	return report, nil
+}
+
+// ListReports lists reports matching filter, newest first.
+func (s *Storage) ListReports(filter ReportFilter) ([]models.ChatReport, error) {
+	filter, err := normalizeReportFilter(filter)
+	if err != nil {
+		return nil, err
+	}
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	if filter.ChannelID != "" {
+		if _, ok := s.data.Channels[filter.ChannelID]; !ok {
+			return nil, notFound("channel", filter.ChannelID)
+		}
+	}
+	reports := make([]models.ChatReport, 0)
+	for _, report := range s.data.ChatReports {
+		if filter.matches(report) {
+			reports = append(reports, report)
+		}
+	}
+	sortReports(reports)
+	return reports, nil
+}
+
+// GetReport returns the report with the given ID.
+func (s *Storage) GetReport(id string) (models.ChatReport, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	report, ok := s.data.ChatReports[id]
+	return report, ok
+}
@@ -15,73 +15,340 @@ type Repository interface {
 	Ping(ctx context.Context) error
 	IngestHealth(ctx context.Context) []ingest.HealthStatus
 	LastIngestHealth() ([]ingest.HealthStatus, time.Time)
+	RecordIngestHealth(statuses []ingest.HealthStatus)
 
 	CreateUser(params CreateUserParams) (models.User, error)
 	AuthenticateUser(email, password string) (models.User, error)
 	AuthenticateOAuth(params OAuthLoginParams) (models.User, error)
-	ListUsers() []models.User
+	ListUsers() ([]models.User, error)
+	ListUsersPage(opts UserListOptions) (UserPage, error)
 	GetUser(id string) (models.User, bool)
 	UpdateUser(id string, update UserUpdate) (models.User, error)
 	SetUserPassword(id, password string) (models.User, error)
+	ConfirmUserBirthDate(id string, birthDate time.Time) (models.User, error)
+	FindUserByUsername(username string) (models.User, bool)
+	ChangeUsername(id, username string, now time.Time) (models.User, error)
 	DeleteUser(id string) error
 
+	CreateAPIToken(params CreateAPITokenParams) (models.APIToken, string, error)
+	ListAPITokens(userID string) ([]models.APIToken, error)
+	RevokeAPIToken(userID, tokenID string) error
+	AuthenticateAPIToken(secret string) (models.APIToken, models.User, error)
+
+	GetNotificationPreferences(userID string) (models.NotificationPreferences, error)
+	UpdateNotificationPreferences(userID string, update NotificationPreferencesUpdate) (models.NotificationPreferences, error)
+	GetPlaybackPreferences(userID string) (models.PlaybackPreferences, error)
+	UpdatePlaybackPreferences(userID string, update PlaybackPreferencesUpdate) (models.PlaybackPreferences, error)
+	CreateNotification(params NotificationParams) (models.Notification, error)
+	ListNotifications(userID string, limit int) ([]models.Notification, error)
+
+	ListBadgeDefinitions() ([]models.BadgeDefinition, error)
+	CreateBadgeDefinition(params BadgeDefinitionParams) (models.BadgeDefinition, error)
+	UpdateBadgeDefinition(slug string, update BadgeDefinitionUpdate) (models.BadgeDefinition, error)
+	DeleteBadgeDefinition(slug string) error
+	GrantBadge(userID, slug, grantedBy string) (models.BadgeGrant, error)
+	RevokeBadge(userID, slug string) error
+	ListUserBadges(userID string) ([]models.BadgeGrant, error)
+	ListBadgeGrants(slug string) ([]models.BadgeGrant, error)
+	ListFeatureFlags() ([]models.FeatureFlag, error)
+	GetFeatureFlag(key string) (models.FeatureFlag, bool)
+	CreateFeatureFlag(params FeatureFlagParams) (models.FeatureFlag, error)
+	UpdateFeatureFlag(key string, update FeatureFlagUpdate) (models.FeatureFlag, error)
+	DeleteFeatureFlag(key string) error
+
 	UpsertProfile(userID string, update ProfileUpdate) (models.Profile, error)
+	// SetProfileImage uploads an avatar or banner to object storage and
+	// points the profile at it, deleting the image it replaces. It reports
+	// ErrObjectStorageUnavailable when no object storage is configured.
+	SetProfileImage(userID string, kind ProfileImageKind, data []byte) (models.Profile, error)
+	// VerifyDonationAddress marks a donation address on the profile as proven
+	// by its owner's wallet signature. address may be empty when the profile
+	// lists a single address for currency.
+	VerifyDonationAddress(userID string, currency models.CryptoCurrency, address string, verifiedAt time.Time) (models.Profile, error)
 	GetProfile(userID string) (models.Profile, bool)
-	ListProfiles() []models.Profile
+	ListProfiles() ([]models.Profile, error)
+	ListProfilesPage(opts UserListOptions) (ProfilePage, error)
 
 	CreateChannel(ownerID, title, category string, tags []string) (models.Channel, error)
         UpdateChannel(id string, update ChannelUpdate) (models.Channel, error)
         RotateChannelStreamKey(id string) (models.Channel, error)
         DeleteChannel(id string) error
         GetChannel(id string) (models.Channel, bool)
-        GetChannelByStreamKey(streamKey string) (models.Channel, bool)
-        ListChannels(ownerID, query string) []models.Channel
+        FindChannelByStreamKeyHash(hash string) (models.Channel, bool)
+	// FindChannelBySlug resolves a current slug, then an earlier one the
+	// channel still redirects from, reporting which in the match.
+	// ChangeChannelSlug renames the channel's slug subject to
+	// ChannelSlugChangeCooldown, keeping the old slug as a redirect.
+	FindChannelBySlug(slug string) (ChannelSlugMatch, bool)
+	ChangeChannelSlug(id, slug string, now time.Time) (models.Channel, error)
+        ListChannels(ownerID, query string) ([]models.Channel, error)
+	// ListChannelsMatching lists the channels passing filter, in ListChannels
+	// order. An unsupported language or warning in the filter is a
+	// ValidationError.
+	ListChannelsMatching(filter ChannelFilter) ([]models.Channel, error)
+	BatchUpdateChannels(ids []string, update ChannelBatchUpdate) ([]ChannelBatchResult, error)
+	ExportChannels(fn func(ChannelExportRow) error) error
+	// ExportChannelSessions, ExportChannelFollows, and ExportChannelRevenue
+	// call fn for each row of a creator's analytics export over [from, to),
+	// which ValidateAnalyticsRange caps at a year.
+	ExportChannelSessions(channelID string, from, to time.Time, fn func(ChannelSessionExportRow) error) error
+	ExportChannelFollows(channelID string, from, to time.Time, fn func(ChannelFollowExportRow) error) error
+	ExportChannelRevenue(channelID string, from, to time.Time, fn func(ChannelRevenueExportRow) error) error
 
 	FollowChannel(userID, channelID string) error
 	UnfollowChannel(userID, channelID string) error
 	IsFollowingChannel(userID, channelID string) bool
 	CountFollowers(channelID string) int
-	ListFollowedChannelIDs(userID string) []string
+	ListFollowedChannelIDs(userID string) ([]string, error)
+	ListChannelFollowers(channelID string, opts FollowerListOptions) (FollowerPage, error)
+	RecommendChannels(userID string, limit int) ([]ChannelRecommendation, error)
+
+	// GrantChannelEditor and GrantChannelModerator take an optional expiry;
+	// expired grants stop applying immediately and are deleted by
+	// PurgeExpiredChannelGrants.
+	GrantChannelEditor(channelID, userID, grantedBy string, expiresAt *time.Time) (models.ChannelEditor, error)
+	RevokeChannelEditor(channelID, userID string) error
+	ListChannelEditors(channelID string) ([]models.ChannelEditor, error)
+	IsChannelEditor(channelID, userID string) bool
+	GrantChannelModerator(channelID, userID, grantedBy string, expiresAt *time.Time) (models.ChannelModerator, error)
+	RevokeChannelModerator(channelID, userID string) error
+	ListChannelModerators(channelID string) ([]models.ChannelModerator, error)
+	IsChannelModerator(channelID, userID string) bool
+	PurgeExpiredChannelGrants() (ExpiredChannelGrants, error)
 
 	StartStream(channelID string, renditions []string) (models.StreamSession, error)
+	// StartStreamContext is StartStream bounded by ctx: ingest is booted
+	// within ctx's deadline and ErrStreamStartTimedOut is returned when it
+	// runs out.
+	StartStreamContext(ctx context.Context, channelID string, renditions []string) (models.StreamSession, error)
+	// StopStream ends the channel's session. The session keeps the peak
+	// recorded through UpdateSessionPeak; peakConcurrent is deprecated and
+	// only raises that peak when it is higher.
 	StopStream(channelID string, peakConcurrent int) (models.StreamSession, error)
+	// StopStreamContext is StopStream bounded by ctx, returning
+	// ErrStreamStopTimedOut when ingest is not torn down in time.
+	StopStreamContext(ctx context.Context, channelID string, peakConcurrent int) (models.StreamSession, error)
+	// UpdateSessionPeak raises a live session's peak concurrent viewers to
+	// concurrent when that is higher and returns the stored peak, which never
+	// decreases. It returns ErrStreamSessionEnded once the session has ended.
+	UpdateSessionPeak(sessionID string, concurrent int) (int, error)
+	RecoverStream(channelID string) (StreamRecovery, error)
 	CurrentStreamSession(channelID string) (models.StreamSession, bool)
+	// RecordStreamMedia notes whether the ingest server reports media
+	// arriving for the channel's live session, returning ErrChannelNotLive
+	// when it has none.
+	RecordStreamMedia(channelID string, receiving bool, at time.Time) (models.StreamSession, error)
+	// ClaimPublishSource pins the live session to the first publish source
+	// reported for it, returning ErrPublishSourceConflict for any other.
+	ClaimPublishSource(channelID, source string) (models.StreamSession, error)
+	CreateSecurityEvent(params SecurityEventParams) (models.SecurityEvent, error)
+	ListSecurityEvents(channelID string, since time.Time) ([]models.SecurityEvent, error)
+	// ListInterruptedStreamSessions returns live sessions whose media
+	// stopped at or before interruptedBefore.
+	ListInterruptedStreamSessions(interruptedBefore time.Time) ([]models.StreamSession, error)
+	ChannelPreview(channelID string) (ingest.Frame, error)
 	ListStreamSessions(channelID string) ([]models.StreamSession, error)
 
+	// CreateCoStreamInvite invites the guest channel to co-stream the host
+	// channel's live session. It returns ErrChannelNotLive when the host is
+	// offline, ErrCoStreamGuestBusy when the guest is live, co-streaming, or
+	// already invited, and ErrCoStreamGuestLimit once the session has
+	// MaxCoStreamGuests active invites.
+	CreateCoStreamInvite(hostChannelID, guestChannelID, invitedBy string) (models.CoStreamInvite, error)
+	GetCoStreamInvite(id string) (models.CoStreamInvite, bool)
+	// RespondCoStreamInvite accepts or declines a pending invite. It returns
+	// ErrCoStreamInviteClosed once the invite was answered or withdrawn and
+	// ErrStreamSessionEnded when the host's stream has ended.
+	RespondCoStreamInvite(id string, accept bool) (models.CoStreamInvite, error)
+	// EndCoStreamInvite withdraws a pending invite or removes an accepted
+	// guest.
+	EndCoStreamInvite(id string) (models.CoStreamInvite, error)
+	// ListCoStreamInvites returns the active invites of live sessions the
+	// channel hosts or is invited to.
+	ListCoStreamInvites(channelID string) ([]models.CoStreamInvite, error)
+	// ListActiveCoStreams returns the accepted invites of every live
+	// session.
+	ListActiveCoStreams() ([]models.CoStreamInvite, error)
+
 	ListRecordings(channelID string, includeUnpublished bool) ([]models.Recording, error)
 	GetRecording(id string) (models.Recording, bool)
+	// RecordingPlayback presigns the recording's manifests in object
+	// storage for ttl, returning ErrRecordingPlaybackUnavailable when they
+	// are not stored there.
+	RecordingPlayback(id string, ttl time.Duration) (RecordingPlayback, error)
 	PublishRecording(id string) (models.Recording, error)
+	// UnpublishRecording hides a published recording from viewers again,
+	// as when a report against it is upheld.
+	UnpublishRecording(id string) (models.Recording, error)
+	// UpdateRecording edits a recording's title, description, and tags
+	// and schedules or cancels its premiere.
+	UpdateRecording(id string, update RecordingUpdate) (models.Recording, error)
+	// BatchUpdateRecordings edits the description and tags of many of a
+	// channel's recordings, reporting the outcome for each.
+	BatchUpdateRecordings(channelID string, ids []string, update RecordingBatchUpdate) ([]RecordingBatchResult, error)
+	// StartDuePremieres publishes every recording whose premiere time has
+	// come, dating it from the premiere, and returns them. Recordings that
+	// are over quota wait until space is freed.
+	StartDuePremieres(now time.Time) ([]models.Recording, error)
+	// ListPremieringRecordings returns the recordings whose premiere is
+	// playing at now, earliest first.
+	ListPremieringRecordings(now time.Time) ([]models.Recording, error)
+	// SetRecordingChapters replaces a recording's chapters. Offsets must
+	// fall inside the recording.
+	SetRecordingChapters(id string, chapters []models.RecordingChapter) (models.Recording, error)
 	DeleteRecording(id string) error
+	PurgeExpiredRecordings(ctx context.Context) error
 
 	CreateUpload(params CreateUploadParams) (models.Upload, error)
 	ListUploads(channelID string) ([]models.Upload, error)
 	GetUpload(id string) (models.Upload, bool)
 	UpdateUpload(id string, update UploadUpdate) (models.Upload, error)
+	// UpdateUploadStep records one pipeline step of an upload and derives
+	// its progress from the steps, without touching the rest of the upload.
+	UpdateUploadStep(id string, step models.UploadStep) error
 	DeleteUpload(id string) error
+	// FindReadyUploadByContentHash returns the most recent ready upload on
+	// the channel whose source media has the given SHA-256. It never looks
+	// beyond the channel.
+	FindReadyUploadByContentHash(channelID, contentHash string) (models.Upload, bool)
 
 	CreateClipExport(recordingID string, params ClipExportParams) (models.ClipExport, error)
 	ListClipExports(recordingID string) ([]models.ClipExport, error)
+	CreateLiveClip(channelID string, params LiveClipParams) (models.ClipExport, error)
+
+	CreateStreamMarker(channelID string, params StreamMarkerParams) (models.StreamMarker, error)
+	ListStreamMarkers(sessionID string) ([]models.StreamMarker, error)
 
-	CreateChatMessage(channelID, userID, content string) (models.ChatMessage, error)
-	DeleteChatMessage(channelID, messageID string) error
+	CreateRestreamTarget(channelID string, params RestreamTargetParams) (models.RestreamTarget, error)
+	ListRestreamTargets(channelID string) ([]models.RestreamTarget, error)
+	UpdateRestreamTarget(channelID, targetID string, update RestreamTargetUpdate) (models.RestreamTarget, error)
+	DeleteRestreamTarget(channelID, targetID string) error
+	RestreamStatus(channelID string) ([]RestreamTargetStatus, error)
+
+	CreateScheduleEntry(channelID string, params ScheduleEntryParams) (models.ScheduleEntry, error)
+	ListScheduleEntries(channelID string) ([]models.ScheduleEntry, error)
+	UpdateScheduleEntry(channelID, entryID string, update ScheduleEntryUpdate) (models.ScheduleEntry, error)
+	DeleteScheduleEntry(channelID, entryID string) error
+	IssueScheduleFeedToken(userID string) (models.ScheduleFeedToken, error)
+	GetScheduleFeedToken(userID string) (models.ScheduleFeedToken, bool)
+	RevokeScheduleFeedToken(userID string) error
+
+	ChannelStorageUsage(channelID string) (ChannelStorageReport, error)
+	SetChannelStorageQuota(channelID string, quotaBytes *int64) (models.ChannelStorage, error)
+
+	// StreamSlotUsage reports how many of the user's channels are starting
+	// or live against the concurrent stream limit they are held to.
+	StreamSlotUsage(userID string) (StreamSlotReport, error)
+	// SetUserStreamLimit sets the admin override of the user's concurrent
+	// stream limit. Nil returns the user to the platform default and zero
+	// lifts the limit.
+	SetUserStreamLimit(userID string, limit *int) (models.User, error)
+
+	CreateChatMessage(channelID, userID, content, clientMessageID string) (models.ChatMessage, error)
+	DeleteChatMessage(channelID, messageID, actorID string) error
 	ListChatMessages(channelID string, limit int) ([]models.ChatMessage, error)
+	PinChatMessage(channelID string, params ChatPinParams) (models.ChatPin, error)
+	UnpinChatMessage(channelID string) (models.ChatPin, error)
+	ChatPin(channelID string) (models.ChatPin, bool)
+	ListChatMessagesInRange(params ChatMessageRangeParams) ([]models.ChatMessage, error)
+	ChatAuthors(channelID string, userIDs []string) (map[string]chat.AuthorInfo, error)
+	HasChatted(channelID, userID string) (bool, error)
+	ChatterStats(channelID string, since time.Time) (ChatterStats, error)
 	ChatRestrictions() chat.RestrictionsSnapshot
 	IsChatBanned(channelID, userID string) bool
 	ChatTimeout(channelID, userID string) (time.Time, bool)
 	ApplyChatEvent(evt chat.Event) error
+	// ApplyChatEvents applies a batch of queued events in order, as one
+	// write where the backend allows. A failed event does not keep the
+	// others from being stored: the returned *ChatEventsError names the
+	// events that failed. Any other error means none were stored.
+	ApplyChatEvents(ctx context.Context, events []chat.Event) error
+	// NextChatSequence and ChatEventsSince back resumable chat
+	// subscriptions; see chat.SequenceStore.
+	NextChatSequence(channelID string) (int64, error)
+	ChatEventsSince(channelID string, afterSeq int64, limit int) ([]chat.Event, error)
 
-	ListChatRestrictions(channelID string) []models.ChatRestriction
+	ListChatRestrictions(channelID string) ([]models.ChatRestriction, error)
 	CreateChatReport(channelID, reporterID, targetID, reason, messageID, evidenceURL string) (models.ChatReport, error)
 	ListChatReports(channelID string, includeResolved bool) ([]models.ChatReport, error)
 	ResolveChatReport(reportID, resolverID, resolution string) (models.ChatReport, error)
+	// CreateReport files a report on a chat message, live session,
+	// recording, or channel. CreateChatReport remains for the chat gateway's
+	// message reports.
+	CreateReport(params ReportParams) (models.ChatReport, error)
+	// ListReports lists reports newest first, across every channel unless
+	// filter.ChannelID names one.
+	ListReports(filter ReportFilter) ([]models.ChatReport, error)
+	GetReport(id string) (models.ChatReport, bool)
+	ListModerationHistory(channelID, targetID string, opts ModerationHistoryOptions) (ModerationHistoryPage, error)
+
+	CreateChatAppeal(channelID, userID, message string) (models.ChatAppeal, error)
+	GetChatAppeal(channelID, appealID string) (models.ChatAppeal, error)
+	ListChatAppeals(channelID string, includeResolved bool) ([]models.ChatAppeal, error)
+	LatestChatAppeal(channelID, userID string) (models.ChatAppeal, bool)
+	ResolveChatAppeal(channelID, appealID, resolverID string, accept bool, note string) (models.ChatAppeal, error)
 
 	CreateTip(params CreateTipParams) (models.Tip, error)
 	ListTips(channelID string, limit int) ([]models.Tip, error)
 
 	CreateSubscription(params CreateSubscriptionParams) (models.Subscription, error)
+	GiftSubscriptions(params GiftSubscriptionsParams) ([]models.Subscription, error)
 	ListSubscriptions(channelID string, includeInactive bool) ([]models.Subscription, error)
 	GetSubscription(id string) (models.Subscription, bool)
 	CancelSubscription(id, cancelledBy, reason string) (models.Subscription, error)
+	SubscriptionStanding(channelID, userID string) (SubscriptionStanding, error)
+
+	EnqueueJob(params EnqueueJobParams) (Job, error)
+	GetJob(id string) (Job, bool)
+	ClaimDueJobs(workerID string, limit int, lease time.Duration) ([]Job, error)
+	CompleteJob(id, workerID string) error
+	FailJob(id, workerID, message string, retryAt time.Time) (Job, error)
+
+	// RollupPlatformStats computes platform-wide aggregates as of now and
+	// stores them as the rollup for now's UTC day, replacing any earlier
+	// rollup of that day.
+	RollupPlatformStats(ctx context.Context, now time.Time) (PlatformStats, error)
+	// LatestPlatformStats returns the most recently computed rollup.
+	LatestPlatformStats() (PlatformStats, bool)
 }
 
 var _ Repository = (*Storage)(nil)
+
+type primaryReadsKey struct{}
+
+// ContextWithPrimaryReads marks ctx as belonging to a request that has
+// written, or is about to write, so later reads must see that write. See
+// RepositoryForContext.
+func ContextWithPrimaryReads(ctx context.Context) context.Context {
+	return context.WithValue(ctx, primaryReadsKey{}, true)
+}
+
+// PrimaryReadsFromContext reports whether ctx was marked by
+// ContextWithPrimaryReads.
+func PrimaryReadsFromContext(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	marked, _ := ctx.Value(primaryReadsKey{}).(bool)
+	return marked
+}
+
+// primaryReader is implemented by repositories that may serve reads from
+// replicas lagging behind the primary.
+type primaryReader interface {
+	withPrimaryReads() Repository
+}
+
+// RepositoryForContext returns the repository to read from on behalf of ctx.
+// Once ctx is marked by ContextWithPrimaryReads, a repository backed by read
+// replicas is swapped for a view that reads only from the primary, so the
+// request sees its own writes. Other repositories are returned unchanged.
+func RepositoryForContext(ctx context.Context, repo Repository) Repository {
+	if !PrimaryReadsFromContext(ctx) {
+		return repo
+	}
+	if reader, ok := repo.(primaryReader); ok {
+		return reader.withPrimaryReads()
+	}
+	return repo
+}
@@ -3,8 +3,10 @@ package storage
 import (
 	"context"
 	"errors"
+	"fmt"
 	"reflect"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -48,19 +50,11 @@ func requireAvailable(t *testing.T, err error, operation string) {
 	}
 }
 
-type retentionRunner interface {
-	runRecordingRetention(ctx context.Context) error
-}
-
 func runRetention(t *testing.T, repo Repository) error {
 	t.Helper()
-	runner, ok := repo.(retentionRunner)
-	if !ok {
-		t.Fatalf("repository does not expose retention runner")
-	}
 	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
 	defer cancel()
-	if err := runner.runRecordingRetention(ctx); err != nil {
+	if err := repo.PurgeExpiredRecordings(ctx); err != nil {
 		if errors.Is(err, ErrPostgresUnavailable) {
 			t.Skip("postgres repository unavailable")
 		}
@@ -134,7 +128,10 @@ func RunRepositoryUserLifecycle(t *testing.T, factory RepositoryFactory) {
 	}
 	requireAvailable(t, repo.DeleteUser(noRoles.ID), "cleanup user without roles")
 
-	users := repo.ListUsers()
+	users, err := repo.ListUsers()
+	if err != nil {
+		t.Fatalf("ListUsers: %v", err)
+	}
 	if len(users) != 2 {
 		t.Fatalf("expected 2 users, got %d", len(users))
 	}
@@ -183,7 +180,10 @@ func RunRepositoryUserLifecycle(t *testing.T, factory RepositoryFactory) {
 		t.Fatalf("expected ErrInvalidCredentials after deletion, got %v", err)
 	}
 
-	remaining := repo.ListUsers()
+	remaining, err := repo.ListUsers()
+	if err != nil {
+		t.Fatalf("ListUsers: %v", err)
+	}
 	if len(remaining) != 1 || remaining[0].ID != admin.ID {
 		t.Fatalf("expected admin to remain after deletion")
 	}
@@ -240,7 +240,189 @@ func RunRepositoryOAuthLinking(t *testing.T, factory RepositoryFactory) {
 	}
 }
 
-// RunRepositoryStreamKeyRotation ensures repositories generate and persist fresh stream keys.
+// RunRepositoryAPITokensLifecycle ensures repositories issue, authenticate,
+// list, and revoke personal access tokens without exposing the secret.
+func RunRepositoryAPITokensLifecycle(t *testing.T, factory RepositoryFactory) {
+	repo := runRepository(t, factory)
+
+	user, err := repo.CreateUser(CreateUserParams{DisplayName: "Bot Owner", Email: "bots@example.com", Roles: []string{"creator"}})
+	requireAvailable(t, err, "create user")
+
+	if _, _, err := repo.CreateAPIToken(CreateAPITokenParams{UserID: user.ID, Name: "deck", Scopes: []string{"admin"}}); err == nil {
+		t.Fatal("expected unsupported scope to be rejected")
+	}
+	past := time.Now().Add(-time.Hour)
+	if _, _, err := repo.CreateAPIToken(CreateAPITokenParams{UserID: user.ID, Name: "deck", ExpiresAt: &past}); err == nil {
+		t.Fatal("expected past expiry to be rejected")
+	}
+
+	token, secret, err := repo.CreateAPIToken(CreateAPITokenParams{UserID: user.ID, Name: " Stream Deck ", Scopes: []string{"chat", "read", "chat"}})
+	requireAvailable(t, err, "create api token")
+	if !strings.HasPrefix(secret, APITokenPrefix) {
+		t.Fatalf("expected secret with %q prefix, got %q", APITokenPrefix, secret)
+	}
+	if token.Name != "Stream Deck" {
+		t.Fatalf("expected trimmed name, got %q", token.Name)
+	}
+	if !reflect.DeepEqual(token.Scopes, []string{"chat", "read"}) {
+		t.Fatalf("expected normalized scopes, got %v", token.Scopes)
+	}
+	if token.TokenHash == "" || strings.Contains(token.TokenHash, secret) {
+		t.Fatalf("expected stored hash distinct from secret, got %q", token.TokenHash)
+	}
+
+	resolvedToken, resolvedUser, err := repo.AuthenticateAPIToken(secret)
+	requireAvailable(t, err, "authenticate api token")
+	if resolvedToken.ID != token.ID || resolvedUser.ID != user.ID {
+		t.Fatalf("expected token %s for user %s, got %s for %s", token.ID, user.ID, resolvedToken.ID, resolvedUser.ID)
+	}
+	if _, _, err := repo.AuthenticateAPIToken(secret + "x"); !errors.Is(err, ErrInvalidAPIToken) {
+		t.Fatalf("expected ErrInvalidAPIToken for unknown secret, got %v", err)
+	}
+
+	tokens, err := repo.ListAPITokens(user.ID)
+	requireAvailable(t, err, "list api tokens")
+	if len(tokens) != 1 || tokens[0].ID != token.ID {
+		t.Fatalf("expected one listed token, got %+v", tokens)
+	}
+
+	other, err := repo.CreateUser(CreateUserParams{DisplayName: "Other", Email: "other@example.com"})
+	requireAvailable(t, err, "create other user")
+	if err := repo.RevokeAPIToken(other.ID, token.ID); !errors.Is(err, ErrAPITokenNotFound) {
+		t.Fatalf("expected ErrAPITokenNotFound revoking another user's token, got %v", err)
+	}
+
+	requireAvailable(t, repo.RevokeAPIToken(user.ID, token.ID), "revoke api token")
+	if _, _, err := repo.AuthenticateAPIToken(secret); !errors.Is(err, ErrInvalidAPIToken) {
+		t.Fatalf("expected revoked token to be rejected, got %v", err)
+	}
+}
+
+// RunRepositoryUserDeactivation ensures repositories soft-deactivate users,
+// refuse their password logins and access tokens, find them by exact email,
+// and reactivate them.
+func RunRepositoryUserDeactivation(t *testing.T, factory RepositoryFactory) {
+	repo := runRepository(t, factory)
+
+	user, err := repo.CreateUser(CreateUserParams{DisplayName: "Provisioned", Email: "Provisioned@Example.com", Password: "correct-horse", Roles: []string{"creator"}})
+	requireAvailable(t, err, "create user")
+	_, err = repo.CreateUser(CreateUserParams{DisplayName: "Lookalike", Email: "not-provisioned@example.com"})
+	requireAvailable(t, err, "create lookalike user")
+	_, secret, err := repo.CreateAPIToken(CreateAPITokenParams{UserID: user.ID, Name: "sync", Scopes: []string{"read"}})
+	requireAvailable(t, err, "create api token")
+
+	page, err := repo.ListUsersPage(UserListOptions{Email: "PROVISIONED@example.com"})
+	requireAvailable(t, err, "list users by email")
+	if page.Total != 1 || len(page.Users) != 1 || page.Users[0].ID != user.ID {
+		t.Fatalf("expected exact email match for %s, got %+v", user.ID, page)
+	}
+
+	deactivate := true
+	updated, err := repo.UpdateUser(user.ID, UserUpdate{Deactivated: &deactivate})
+	requireAvailable(t, err, "deactivate user")
+	if updated.DeactivatedAt == nil {
+		t.Fatal("expected deactivation time to be set")
+	}
+	deactivatedAt := *updated.DeactivatedAt
+	again, err := repo.UpdateUser(user.ID, UserUpdate{Deactivated: &deactivate})
+	requireAvailable(t, err, "deactivate user again")
+	if again.DeactivatedAt == nil || !again.DeactivatedAt.Equal(deactivatedAt) {
+		t.Fatalf("expected repeated deactivation to keep %s, got %v", deactivatedAt, again.DeactivatedAt)
+	}
+	stored, ok := repo.GetUser(user.ID)
+	if !ok || !stored.Deactivated() || len(stored.Roles) != 1 {
+		t.Fatalf("expected user kept with roles after deactivation, got %+v", stored)
+	}
+	if _, err := repo.AuthenticateUser(user.Email, "correct-horse"); !errors.Is(err, ErrUserDeactivated) {
+		t.Fatalf("expected ErrUserDeactivated at login, got %v", err)
+	}
+	if _, err := repo.AuthenticateUser(user.Email, "wrong-horse"); !errors.Is(err, ErrInvalidCredentials) {
+		t.Fatalf("expected a wrong password to stay ErrInvalidCredentials, got %v", err)
+	}
+	if _, _, err := repo.AuthenticateAPIToken(secret); !errors.Is(err, ErrInvalidAPIToken) {
+		t.Fatalf("expected access token of a deactivated user to be refused, got %v", err)
+	}
+
+	reactivate := false
+	updated, err = repo.UpdateUser(user.ID, UserUpdate{Deactivated: &reactivate})
+	requireAvailable(t, err, "reactivate user")
+	if updated.Deactivated() {
+		t.Fatalf("expected user to be reactivated, got %+v", updated.DeactivatedAt)
+	}
+	if _, err := repo.AuthenticateUser(user.Email, "correct-horse"); err != nil {
+		t.Fatalf("expected login after reactivation, got %v", err)
+	}
+	if _, _, err := repo.AuthenticateAPIToken(secret); err != nil {
+		t.Fatalf("expected access token to work after reactivation, got %v", err)
+	}
+}
+
+// RunRepositoryNotificationPreferences ensures repositories materialise
+// defaults, apply partial updates, and drop preferences with their user.
+func RunRepositoryNotificationPreferences(t *testing.T, factory RepositoryFactory) {
+	repo := runRepository(t, factory)
+
+	user, err := repo.CreateUser(CreateUserParams{DisplayName: "Notified", Email: "notified@example.com"})
+	requireAvailable(t, err, "create user")
+
+	prefs, err := repo.GetNotificationPreferences(user.ID)
+	requireAvailable(t, err, "get default preferences")
+	if want := models.DefaultNotificationPreferences(user.ID); !reflect.DeepEqual(prefs, want) {
+		t.Fatalf("expected default preferences %+v, got %+v", want, prefs)
+	}
+
+	disabled, enabled := false, true
+	prefs, err = repo.UpdateNotificationPreferences(user.ID, NotificationPreferencesUpdate{
+		Mentions: &NotificationDeliveryUpdate{Email: &enabled},
+		Account:  &NotificationDeliveryUpdate{InApp: &disabled},
+	})
+	requireAvailable(t, err, "update preferences")
+	if !prefs.Mentions.InApp || !prefs.Mentions.Email {
+		t.Fatalf("expected mentions in-app kept and email enabled, got %+v", prefs.Mentions)
+	}
+	if prefs.Account.InApp || !prefs.Account.Email {
+		t.Fatalf("expected account in-app disabled and email kept, got %+v", prefs.Account)
+	}
+	if prefs.FollowedLive != (models.NotificationDelivery{InApp: true}) {
+		t.Fatalf("expected untouched categories to keep defaults, got %+v", prefs.FollowedLive)
+	}
+	if prefs.UpdatedAt == nil {
+		t.Fatal("expected updatedAt after an update")
+	}
+
+	prefs, err = repo.UpdateNotificationPreferences(user.ID, NotificationPreferencesUpdate{
+		FollowedLive: &NotificationDeliveryUpdate{InApp: &disabled},
+	})
+	requireAvailable(t, err, "second update")
+	if prefs.FollowedLive.InApp || !prefs.Mentions.Email || prefs.Account.InApp {
+		t.Fatalf("expected earlier changes to survive a later update, got %+v", prefs)
+	}
+	stored, err := repo.GetNotificationPreferences(user.ID)
+	requireAvailable(t, err, "reload preferences")
+	if stored.FollowedLive != prefs.FollowedLive || stored.Mentions != prefs.Mentions || stored.Account != prefs.Account || stored.Moderation != prefs.Moderation {
+		t.Fatalf("expected stored preferences %+v, got %+v", prefs, stored)
+	}
+	if stored.Allows(models.NotificationCategoryFollowedLive, models.NotificationDeliveryInApp) {
+		t.Fatal("expected disabled category to refuse in-app delivery")
+	}
+
+	if _, err := repo.GetNotificationPreferences("missing"); err == nil {
+		t.Fatal("expected error for unknown user")
+	}
+	if _, err := repo.UpdateNotificationPreferences("missing", NotificationPreferencesUpdate{}); err == nil {
+		t.Fatal("expected update for unknown user to fail")
+	}
+
+	if err := repo.DeleteUser(user.ID); err != nil {
+		t.Fatalf("delete user: %v", err)
+	}
+	if _, err := repo.GetNotificationPreferences(user.ID); err == nil {
+		t.Fatal("expected preferences to go away with the user")
+	}
+}
+
+// RunRepositoryStreamKeyRotation ensures repositories generate fresh stream
+// keys, return the plaintext once, and persist only the hash.
 func RunRepositoryStreamKeyRotation(t *testing.T, factory RepositoryFactory) {
 	repo := runRepository(t, factory)
 
@@ -252,6 +434,9 @@ func RunRepositoryStreamKeyRotation(t *testing.T, factory RepositoryFactory) {
 	if channel.StreamKey == "" {
 		t.Fatal("expected initial stream key")
 	}
+	if channel.StreamKeyHash != HashStreamKey(channel.StreamKey) {
+		t.Fatalf("expected created channel to carry the key hash")
+	}
 
 	originalKey := channel.StreamKey
 	rotated, err := repo.RotateChannelStreamKey(channel.ID)
@@ -262,24 +447,39 @@ func RunRepositoryStreamKeyRotation(t *testing.T, factory RepositoryFactory) {
 	if rotated.StreamKey == originalKey {
 		t.Fatalf("expected rotated stream key to differ from original %q", originalKey)
 	}
+	if !strings.HasPrefix(rotated.StreamKeyHint, rotated.StreamKey[:4]) || !strings.HasSuffix(rotated.StreamKeyHint, rotated.StreamKey[len(rotated.StreamKey)-4:]) {
+		t.Fatalf("expected hint %q to show the ends of the key", rotated.StreamKeyHint)
+	}
 
 	fetched, ok := repo.GetChannel(channel.ID)
 	if !ok {
 		t.Fatalf("expected channel %s to remain after rotation", channel.ID)
 	}
-	if fetched.StreamKey != rotated.StreamKey {
-		t.Fatalf("expected fetched stream key %q, got %q", rotated.StreamKey, fetched.StreamKey)
+	if fetched.StreamKey != "" {
+		t.Fatalf("expected fetched channel to omit the plaintext key, got %q", fetched.StreamKey)
+	}
+	if !StreamKeyMatches(fetched, rotated.StreamKey) {
+		t.Fatal("expected rotated key to match the stored hash")
+	}
+	if StreamKeyMatches(fetched, originalKey) {
+		t.Fatal("expected original key to stop matching after rotation")
+	}
+	if fetched.StreamKeyHint != rotated.StreamKeyHint {
+		t.Fatalf("expected stored hint %q, got %q", rotated.StreamKeyHint, fetched.StreamKeyHint)
 	}
 
-	channels := repo.ListChannels(owner.ID, "")
+	channels, err := repo.ListChannels(owner.ID, "")
+	if err != nil {
+		t.Fatalf("ListChannels: %v", err)
+	}
 	found := false
 	for _, item := range channels {
 		if item.ID != channel.ID {
 			continue
 		}
 		found = true
-		if item.StreamKey != rotated.StreamKey {
-			t.Fatalf("expected listed stream key %q, got %q", rotated.StreamKey, item.StreamKey)
+		if item.StreamKey != "" || item.StreamKeyHash != rotated.StreamKeyHash {
+			t.Fatalf("expected listed channel to carry only the rotated hash, got %+v", item)
 		}
 	}
 	if !found {
@@ -306,57 +506,798 @@ func RunRepositoryChannelSearch(t *testing.T, factory RepositoryFactory) {
 	beats, err := repo.CreateChannel(creatorThree.ID, "Midnight Beats", "music", []string{"Live", "Music"})
 	requireAvailable(t, err, "create midnight beats")
 
-	if channels := repo.ListChannels("", ""); len(channels) != 3 {
+	channels, err := repo.ListChannels("", "")
+	if err != nil {
+		t.Fatalf("ListChannels: %v", err)
+	}
+	if len(channels) != 3 {
 		t.Fatalf("expected 3 channels without filter, got %d", len(channels))
 	}
 
-	cases := []struct {
-		name    string
-		ownerID string
-		query   string
-		wantIDs []string
-	}{
-		{name: "title query", query: "lounge", wantIDs: []string{lounge.ID}},
-		{name: "owner display name", query: "RETROMASTER", wantIDs: []string{arcade.ID}},
-		{name: "tag mixed case", query: "MuSiC", wantIDs: []string{beats.ID}},
-		{name: "owner scoped", ownerID: creatorTwo.ID, query: "ARCADE", wantIDs: []string{arcade.ID}},
-		{name: "no matches", query: "unknown", wantIDs: []string{}},
+	cases := []struct {
+		name    string
+		ownerID string
+		query   string
+		wantIDs []string
+	}{
+		{name: "title query", query: "lounge", wantIDs: []string{lounge.ID}},
+		{name: "owner display name", query: "RETROMASTER", wantIDs: []string{arcade.ID}},
+		{name: "tag mixed case", query: "MuSiC", wantIDs: []string{beats.ID}},
+		{name: "owner scoped", ownerID: creatorTwo.ID, query: "ARCADE", wantIDs: []string{arcade.ID}},
+		{name: "no matches", query: "unknown", wantIDs: []string{}},
+	}
+
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			channels, err := repo.ListChannels(tc.ownerID, tc.query)
+			if err != nil {
+				t.Fatalf("ListChannels: %v", err)
+			}
+			if len(channels) != len(tc.wantIDs) {
+				t.Fatalf("expected %d channels, got %d", len(tc.wantIDs), len(channels))
+			}
+			for i, id := range tc.wantIDs {
+				if channels[i].ID != id {
+					t.Fatalf("expected channel %s at index %d, got %s", id, i, channels[i].ID)
+				}
+			}
+		})
+	}
+}
+
+// RunRepositoryUserListing verifies paged user and profile listings filter by
+// search text and role, order by each sort key with ID tie-breaks, and report
+// totals across pages.
+func RunRepositoryUserListing(t *testing.T, factory RepositoryFactory) {
+	repo := runRepository(t, factory)
+
+	alice, err := repo.CreateUser(CreateUserParams{DisplayName: "Alice Admin", Email: "alice@example.com", Roles: []string{"admin"}})
+	requireAvailable(t, err, "create alice")
+	bob, err := repo.CreateUser(CreateUserParams{DisplayName: "bob", Email: "bob@example.com", Roles: []string{"creator"}})
+	requireAvailable(t, err, "create bob")
+	carol, err := repo.CreateUser(CreateUserParams{DisplayName: "Carol", Email: "carol@corp.test", Roles: []string{"creator", "moderator"}})
+	requireAvailable(t, err, "create carol")
+	dave, err := repo.CreateUser(CreateUserParams{DisplayName: "Dave", Email: "dave@corp.test"})
+	requireAvailable(t, err, "create dave")
+	samOne, err := repo.CreateUser(CreateUserParams{DisplayName: "Sam", Email: "sam1@example.com"})
+	requireAvailable(t, err, "create first sam")
+	samTwo, err := repo.CreateUser(CreateUserParams{DisplayName: "Sam", Email: "sam2@example.com"})
+	requireAvailable(t, err, "create second sam")
+	samLow, samHigh := samOne.ID, samTwo.ID
+	if samHigh < samLow {
+		samLow, samHigh = samHigh, samLow
+	}
+
+	cases := []struct {
+		name      string
+		opts      UserListOptions
+		wantIDs   []string
+		wantTotal int
+	}{
+		{name: "defaults to creation order", opts: UserListOptions{}, wantIDs: []string{alice.ID, bob.ID, carol.ID, dave.ID, samOne.ID, samTwo.ID}, wantTotal: 6},
+		{name: "search matches email", opts: UserListOptions{Query: "corp"}, wantIDs: []string{carol.ID, dave.ID}, wantTotal: 2},
+		{name: "search ignores case", opts: UserListOptions{Query: "ALICE"}, wantIDs: []string{alice.ID}, wantTotal: 1},
+		{name: "name only skips email", opts: UserListOptions{Query: "corp", NameOnly: true}, wantIDs: []string{}, wantTotal: 0},
+		{name: "wildcards match literally", opts: UserListOptions{Query: "%"}, wantIDs: []string{}, wantTotal: 0},
+		{name: "role filter", opts: UserListOptions{Role: "creator"}, wantIDs: []string{bob.ID, carol.ID}, wantTotal: 2},
+		{name: "search and role", opts: UserListOptions{Query: "corp", Role: "creator"}, wantIDs: []string{carol.ID}, wantTotal: 1},
+		{name: "display name ascending", opts: UserListOptions{Sort: UserSortDisplayName}, wantIDs: []string{alice.ID, bob.ID, carol.ID, dave.ID, samLow, samHigh}, wantTotal: 6},
+		{name: "display name descending", opts: UserListOptions{Sort: UserSortDisplayName, Descending: true}, wantIDs: []string{samHigh, samLow, dave.ID, carol.ID, bob.ID, alice.ID}, wantTotal: 6},
+		{name: "email descending", opts: UserListOptions{Sort: UserSortEmail, Descending: true, Limit: 3}, wantIDs: []string{samTwo.ID, samOne.ID, dave.ID}, wantTotal: 6},
+		{name: "created descending", opts: UserListOptions{Sort: UserSortCreatedAt, Descending: true, Limit: 2}, wantIDs: []string{samTwo.ID, samOne.ID}, wantTotal: 6},
+		{name: "middle page", opts: UserListOptions{Sort: UserSortDisplayName, Limit: 2, Offset: 2}, wantIDs: []string{carol.ID, dave.ID}, wantTotal: 6},
+		{name: "tied names page stably", opts: UserListOptions{Sort: UserSortDisplayName, Limit: 1, Offset: 5}, wantIDs: []string{samHigh}, wantTotal: 6},
+		{name: "past the end", opts: UserListOptions{Limit: 10, Offset: 10}, wantIDs: []string{}, wantTotal: 6},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			page, err := repo.ListUsersPage(tc.opts)
+			if err != nil {
+				t.Fatalf("ListUsersPage: %v", err)
+			}
+			if page.Total != tc.wantTotal {
+				t.Fatalf("expected total %d, got %d", tc.wantTotal, page.Total)
+			}
+			got := make([]string, 0, len(page.Users))
+			for _, user := range page.Users {
+				got = append(got, user.ID)
+			}
+			if strings.Join(got, ",") != strings.Join(tc.wantIDs, ",") {
+				t.Fatalf("expected users %v, got %v", tc.wantIDs, got)
+			}
+		})
+	}
+
+	if _, err := repo.ListUsersPage(UserListOptions{Sort: "password_hash"}); err == nil {
+		t.Fatal("expected unsupported sort to fail")
+	}
+
+	bio := "hello"
+	for _, user := range []models.User{alice, carol, samOne} {
+		_, err := repo.UpsertProfile(user.ID, ProfileUpdate{Bio: &bio})
+		requireAvailable(t, err, "upsert profile")
+	}
+	profiles, err := repo.ListProfilesPage(UserListOptions{Sort: UserSortDisplayName, Descending: true})
+	if err != nil {
+		t.Fatalf("ListProfilesPage: %v", err)
+	}
+	if profiles.Total != 3 || len(profiles.Profiles) != 3 {
+		t.Fatalf("expected 3 profiles, got total=%d len=%d", profiles.Total, len(profiles.Profiles))
+	}
+	if profiles.Profiles[0].UserID != samOne.ID || profiles.Profiles[1].UserID != carol.ID || profiles.Profiles[2].UserID != alice.ID {
+		t.Fatalf("unexpected profile order %+v", profiles.Profiles)
+	}
+	profiles, err = repo.ListProfilesPage(UserListOptions{Role: "creator"})
+	if err != nil {
+		t.Fatalf("ListProfilesPage by role: %v", err)
+	}
+	if profiles.Total != 1 || len(profiles.Profiles) != 1 || profiles.Profiles[0].UserID != carol.ID {
+		t.Fatalf("expected only carol's profile for the creator role, got %+v", profiles)
+	}
+	profiles, err = repo.ListProfilesPage(UserListOptions{Query: "example", NameOnly: true, Limit: 1})
+	if err != nil {
+		t.Fatalf("ListProfilesPage name only: %v", err)
+	}
+	if profiles.Total != 0 || len(profiles.Profiles) != 0 {
+		t.Fatalf("expected no profiles when searching names for an email domain, got %+v", profiles)
+	}
+	profiles, err = repo.ListProfilesPage(UserListOptions{Query: "example", Limit: 1, Offset: 1})
+	if err != nil {
+		t.Fatalf("ListProfilesPage paged: %v", err)
+	}
+	if profiles.Total != 2 || len(profiles.Profiles) != 1 || profiles.Profiles[0].UserID != samOne.ID {
+		t.Fatalf("expected second example.com profile on page two, got %+v", profiles)
+	}
+	if profiles.Profiles[0].SocialLinks == nil || profiles.Profiles[0].TopFriends == nil || profiles.Profiles[0].DonationAddresses == nil {
+		t.Fatalf("expected profile collections to be non-nil, got %+v", profiles.Profiles[0])
+	}
+}
+
+// RunRepositoryChannelLookupByStreamKey ensures repositories can resolve
+// channels from the hash of their stream key.
+func RunRepositoryChannelLookupByStreamKey(t *testing.T, factory RepositoryFactory) {
+	repo := runRepository(t, factory)
+
+	owner, err := repo.CreateUser(CreateUserParams{DisplayName: "Owner", Email: "owner@example.com"})
+	requireAvailable(t, err, "create owner")
+	channel, err := repo.CreateChannel(owner.ID, "Live", "gaming", []string{"rpg"})
+	requireAvailable(t, err, "create channel")
+
+	fetched, ok := repo.FindChannelByStreamKeyHash(HashStreamKey(channel.StreamKey))
+	if !ok {
+		t.Fatal("expected channel to be found by stream key hash")
+	}
+	if fetched.ID != channel.ID {
+		t.Fatalf("expected channel %s, got %s", channel.ID, fetched.ID)
+	}
+
+	if _, ok := repo.FindChannelByStreamKeyHash(HashStreamKey("missing-key")); ok {
+		t.Fatal("expected missing stream key to return ok=false")
+	}
+	if _, ok := repo.FindChannelByStreamKeyHash(channel.StreamKey); ok {
+		t.Fatal("expected plaintext key not to be accepted as a hash")
+	}
+}
+
+// RunRepositoryChannelPlaybackRestriction verifies playback restrictions are
+// validated, persisted, and returned by every channel lookup.
+func RunRepositoryChannelPlaybackRestriction(t *testing.T, factory RepositoryFactory) {
+	repo := runRepository(t, factory)
+
+	owner, err := repo.CreateUser(CreateUserParams{DisplayName: "Owner", Email: "owner@example.com"})
+	requireAvailable(t, err, "create owner")
+	channel, err := repo.CreateChannel(owner.ID, "Members", "music", nil)
+	requireAvailable(t, err, "create channel")
+	if channel.PlaybackRestriction != "" || channel.PlaybackPreviews {
+		t.Fatalf("expected new channel to be unrestricted, got %q previews=%v", channel.PlaybackRestriction, channel.PlaybackPreviews)
+	}
+
+	invalid := "everyone"
+	if _, err := repo.UpdateChannel(channel.ID, ChannelUpdate{PlaybackRestriction: &invalid}); err == nil {
+		t.Fatal("expected invalid playback restriction to be rejected")
+	}
+
+	restriction := " Subscribers "
+	previews := true
+	updated, err := repo.UpdateChannel(channel.ID, ChannelUpdate{PlaybackRestriction: &restriction, PlaybackPreviews: &previews})
+	if err != nil {
+		t.Fatalf("UpdateChannel: %v", err)
+	}
+	if updated.PlaybackRestriction != models.PlaybackRestrictionSubscribers || !updated.PlaybackPreviews {
+		t.Fatalf("expected subscribers restriction with previews, got %q previews=%v", updated.PlaybackRestriction, updated.PlaybackPreviews)
+	}
+	fetched, ok := repo.GetChannel(channel.ID)
+	if !ok || fetched.PlaybackRestriction != models.PlaybackRestrictionSubscribers || !fetched.PlaybackPreviews {
+		t.Fatalf("expected restriction to persist, got %+v", fetched)
+	}
+	listed, err := repo.ListChannels(owner.ID, "")
+	if err != nil {
+		t.Fatalf("ListChannels: %v", err)
+	}
+	if len(listed) != 1 || listed[0].PlaybackRestriction != models.PlaybackRestrictionSubscribers {
+		t.Fatalf("expected listed channel to carry restriction, got %+v", listed)
+	}
+
+	none := "none"
+	cleared, err := repo.UpdateChannel(channel.ID, ChannelUpdate{PlaybackRestriction: &none})
+	if err != nil {
+		t.Fatalf("UpdateChannel clear: %v", err)
+	}
+	if cleared.PlaybackRestriction != "" || !cleared.PlaybackPreviews {
+		t.Fatalf("expected restriction cleared and previews untouched, got %q previews=%v", cleared.PlaybackRestriction, cleared.PlaybackPreviews)
+	}
+}
+
+// RunRepositoryChannelTranscodeLimits verifies the per-channel transcode cap
+// override is validated, persisted, returned by channel lookups, and cleared
+// by an all-zero update.
+func RunRepositoryChannelTranscodeLimits(t *testing.T, factory RepositoryFactory) {
+	repo := runRepository(t, factory)
+
+	owner, err := repo.CreateUser(CreateUserParams{DisplayName: "Owner", Email: "owner@example.com"})
+	requireAvailable(t, err, "create owner")
+	channel, err := repo.CreateChannel(owner.ID, "Caps", "music", nil)
+	requireAvailable(t, err, "create channel")
+	if channel.TranscodeLimits != nil {
+		t.Fatalf("expected new channel to follow platform limits, got %+v", channel.TranscodeLimits)
+	}
+
+	if _, err := repo.UpdateChannel(channel.ID, ChannelUpdate{TranscodeLimits: &models.TranscodeLimits{MaxBitrate: -1}}); err == nil {
+		t.Fatal("expected negative limits to be rejected")
+	}
+
+	override := models.TranscodeLimits{MaxHeight: 1080, MaxBitrate: 9000, MaxRenditions: 3}
+	updated, err := repo.UpdateChannel(channel.ID, ChannelUpdate{TranscodeLimits: &override})
+	if err != nil {
+		t.Fatalf("UpdateChannel: %v", err)
+	}
+	if updated.TranscodeLimits == nil || *updated.TranscodeLimits != override {
+		t.Fatalf("expected override %+v, got %+v", override, updated.TranscodeLimits)
+	}
+	fetched, ok := repo.GetChannel(channel.ID)
+	if !ok || fetched.TranscodeLimits == nil || *fetched.TranscodeLimits != override {
+		t.Fatalf("expected override to persist, got %+v", fetched.TranscodeLimits)
+	}
+	listed, err := repo.ListChannels(owner.ID, "")
+	if err != nil {
+		t.Fatalf("ListChannels: %v", err)
+	}
+	if len(listed) != 1 || listed[0].TranscodeLimits == nil || *listed[0].TranscodeLimits != override {
+		t.Fatalf("expected listed channel to carry override, got %+v", listed)
+	}
+
+	title := "Renamed"
+	renamed, err := repo.UpdateChannel(channel.ID, ChannelUpdate{Title: &title})
+	if err != nil {
+		t.Fatalf("UpdateChannel title: %v", err)
+	}
+	if renamed.TranscodeLimits == nil || *renamed.TranscodeLimits != override {
+		t.Fatalf("expected unrelated update to keep override, got %+v", renamed.TranscodeLimits)
+	}
+
+	cleared, err := repo.UpdateChannel(channel.ID, ChannelUpdate{TranscodeLimits: &models.TranscodeLimits{}})
+	if err != nil {
+		t.Fatalf("UpdateChannel clear: %v", err)
+	}
+	if cleared.TranscodeLimits != nil {
+		t.Fatalf("expected override cleared, got %+v", cleared.TranscodeLimits)
+	}
+	if fetched, _ := repo.GetChannel(channel.ID); fetched.TranscodeLimits != nil {
+		t.Fatalf("expected cleared override to persist, got %+v", fetched.TranscodeLimits)
+	}
+}
+
+// RunRepositoryMatureContentAndAgeConfirmation verifies the channel mature
+// content flag persists and that a user's birth date can only be set once.
+func RunRepositoryMatureContentAndAgeConfirmation(t *testing.T, factory RepositoryFactory) {
+	repo := runRepository(t, factory)
+
+	owner, err := repo.CreateUser(CreateUserParams{DisplayName: "Owner", Email: "owner@example.com"})
+	requireAvailable(t, err, "create owner")
+	channel, err := repo.CreateChannel(owner.ID, "Late night", "talk", nil)
+	requireAvailable(t, err, "create channel")
+	if channel.MatureContent {
+		t.Fatal("expected new channel not to be mature")
+	}
+	mature := true
+	if _, err := repo.UpdateChannel(channel.ID, ChannelUpdate{MatureContent: &mature}); err != nil {
+		t.Fatalf("UpdateChannel mature: %v", err)
+	}
+	fetched, ok := repo.GetChannel(channel.ID)
+	if !ok || !fetched.MatureContent {
+		t.Fatalf("expected mature flag to persist, got %+v", fetched)
+	}
+	listed, err := repo.ListChannels(owner.ID, "")
+	if err != nil {
+		t.Fatalf("ListChannels: %v", err)
+	}
+	if len(listed) != 1 || !listed[0].MatureContent {
+		t.Fatalf("expected listed channel to carry the mature flag, got %+v", listed)
+	}
+
+	if _, err := repo.ConfirmUserBirthDate(owner.ID, time.Now().Add(48*time.Hour)); err == nil {
+		t.Fatal("expected a future birth date to be rejected")
+	}
+	born := time.Date(1990, time.June, 15, 22, 30, 0, 0, time.FixedZone("UTC-5", -5*60*60))
+	confirmed, err := repo.ConfirmUserBirthDate(owner.ID, born)
+	if err != nil {
+		t.Fatalf("ConfirmUserBirthDate: %v", err)
+	}
+	want := time.Date(1990, time.June, 15, 0, 0, 0, 0, time.UTC)
+	if confirmed.BirthDate == nil || !confirmed.BirthDate.Equal(want) || confirmed.AgeConfirmedAt == nil {
+		t.Fatalf("expected birth date %s with a confirmation time, got %v / %v", want, confirmed.BirthDate, confirmed.AgeConfirmedAt)
+	}
+	reloaded, ok := repo.GetUser(owner.ID)
+	if !ok || reloaded.BirthDate == nil || !reloaded.BirthDate.Equal(want) || reloaded.AgeConfirmedAt == nil {
+		t.Fatalf("expected birth date to persist, got %+v", reloaded)
+	}
+	if _, err := repo.ConfirmUserBirthDate(owner.ID, want.AddDate(-1, 0, 0)); !errors.Is(err, ErrBirthDateAlreadySet) {
+		t.Fatalf("expected ErrBirthDateAlreadySet on a second confirmation, got %v", err)
+	}
+	if _, err := repo.ConfirmUserBirthDate("missing", want); err == nil || errors.Is(err, ErrBirthDateAlreadySet) {
+		t.Fatalf("expected unknown user error, got %v", err)
+	}
+}
+
+// RunRepositoryChannelBatchUpdate verifies batch category and tag updates
+// report per-channel outcomes, validate categories, and show up in exports.
+func RunRepositoryChannelBatchUpdate(t *testing.T, factory RepositoryFactory) {
+	repo := runRepository(t, factory)
+
+	owner, err := repo.CreateUser(CreateUserParams{DisplayName: "Owner", Email: "owner@example.com"})
+	requireAvailable(t, err, "create owner")
+	first, err := repo.CreateChannel(owner.ID, "First", "gaming", []string{"speedrun", "retro"})
+	requireAvailable(t, err, "create first channel")
+	second, err := repo.CreateChannel(owner.ID, "Second", "music", []string{"retro"})
+	requireAvailable(t, err, "create second channel")
+	fan, err := repo.CreateUser(CreateUserParams{DisplayName: "Fan", Email: "fan@example.com"})
+	requireAvailable(t, err, "create fan")
+	if err := repo.FollowChannel(fan.ID, second.ID); err != nil {
+		t.Fatalf("FollowChannel: %v", err)
+	}
+
+	tooLong := strings.Repeat("x", MaxChannelCategoryLength+1)
+	if _, err := repo.BatchUpdateChannels([]string{first.ID}, ChannelBatchUpdate{Category: &tooLong}); err == nil {
+		t.Fatal("expected overlong category to be rejected")
+	}
+	if _, err := repo.BatchUpdateChannels([]string{first.ID}, ChannelBatchUpdate{}); err == nil {
+		t.Fatal("expected empty batch update to be rejected")
+	}
+
+	category := " music "
+	results, err := repo.BatchUpdateChannels(
+		[]string{first.ID, second.ID, "missing", first.ID},
+		ChannelBatchUpdate{Category: &category, RemoveTags: []string{"speedrun"}},
+	)
+	if err != nil {
+		t.Fatalf("BatchUpdateChannels: %v", err)
+	}
+	statuses := make([]string, 0, len(results))
+	for _, result := range results {
+		statuses = append(statuses, result.ChannelID+"="+result.Status)
+	}
+	expected := []string{first.ID + "=" + ChannelBatchUpdated, second.ID + "=" + ChannelBatchSkipped, "missing=" + ChannelBatchFailed}
+	if !reflect.DeepEqual(statuses, expected) {
+		t.Fatalf("expected results %v, got %v", expected, statuses)
+	}
+	if results[2].Error == "" {
+		t.Fatal("expected missing channel to carry an error")
+	}
+	updated, ok := repo.GetChannel(first.ID)
+	if !ok || updated.Category != "music" || !reflect.DeepEqual(updated.Tags, []string{"retro"}) {
+		t.Fatalf("expected first channel re-categorized without speedrun tag, got %+v", updated)
+	}
+
+	results, err = repo.BatchUpdateChannels([]string{first.ID, second.ID}, ChannelBatchUpdate{AddTags: []string{"Chill"}})
+	if err != nil {
+		t.Fatalf("BatchUpdateChannels add tags: %v", err)
+	}
+	for _, result := range results {
+		if result.Status != ChannelBatchUpdated {
+			t.Fatalf("expected tag addition to update %s, got %s", result.ChannelID, result.Status)
+		}
+	}
+
+	var exported []ChannelExportRow
+	if err := repo.ExportChannels(func(row ChannelExportRow) error {
+		exported = append(exported, row)
+		return nil
+	}); err != nil {
+		t.Fatalf("ExportChannels: %v", err)
+	}
+	if len(exported) != 2 || exported[0].Channel.ID != first.ID || exported[1].Channel.ID != second.ID {
+		t.Fatalf("expected both channels oldest first, got %+v", exported)
+	}
+	if exported[1].OwnerEmail != "owner@example.com" || exported[1].Followers != 1 || !reflect.DeepEqual(exported[1].Channel.Tags, []string{"chill", "retro"}) {
+		t.Fatalf("unexpected export row %+v", exported[1])
+	}
+	stop := errors.New("stop")
+	if err := repo.ExportChannels(func(ChannelExportRow) error { return stop }); !errors.Is(err, stop) {
+		t.Fatalf("expected export to stop on callback error, got %v", err)
+	}
+}
+
+// RunRepositoryChannelEditorGrants verifies per-channel editor grants are
+// restricted to editors, idempotent, and cleaned up when revoked.
+func RunRepositoryChannelEditorGrants(t *testing.T, factory RepositoryFactory) {
+	repo := runRepository(t, factory)
+
+	owner, err := repo.CreateUser(CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	requireAvailable(t, err, "create owner")
+	editor, err := repo.CreateUser(CreateUserParams{DisplayName: "Editor", Email: "editor@example.com", Roles: []string{"editor"}})
+	requireAvailable(t, err, "create editor")
+	viewer, err := repo.CreateUser(CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	requireAvailable(t, err, "create viewer")
+	channel, err := repo.CreateChannel(owner.ID, "Studio", "gaming", nil)
+	requireAvailable(t, err, "create channel")
+
+	if _, err := repo.GrantChannelEditor(channel.ID, viewer.ID, owner.ID, nil); err == nil {
+		t.Fatal("expected grant to a user without the editor role to fail")
+	}
+	if _, err := repo.GrantChannelEditor("missing", editor.ID, owner.ID, nil); err == nil {
+		t.Fatal("expected grant on a missing channel to fail")
+	}
+
+	grant, err := repo.GrantChannelEditor(channel.ID, editor.ID, owner.ID, nil)
+	if err != nil {
+		t.Fatalf("GrantChannelEditor: %v", err)
+	}
+	if grant.ChannelID != channel.ID || grant.UserID != editor.ID || grant.GrantedBy != owner.ID || grant.GrantedAt.IsZero() {
+		t.Fatalf("unexpected grant %+v", grant)
+	}
+	again, err := repo.GrantChannelEditor(channel.ID, editor.ID, "", nil)
+	if err != nil {
+		t.Fatalf("repeat GrantChannelEditor: %v", err)
+	}
+	if again.GrantedBy != owner.ID {
+		t.Fatalf("expected repeat grant to keep original grantor, got %q", again.GrantedBy)
+	}
+	if !repo.IsChannelEditor(channel.ID, editor.ID) {
+		t.Fatal("expected editor grant to be recorded")
+	}
+	if repo.IsChannelEditor(channel.ID, viewer.ID) {
+		t.Fatal("expected viewer not to be an editor")
+	}
+
+	editors, err := repo.ListChannelEditors(channel.ID)
+	if err != nil {
+		t.Fatalf("ListChannelEditors: %v", err)
+	}
+	if len(editors) != 1 || editors[0].UserID != editor.ID {
+		t.Fatalf("expected single editor grant, got %+v", editors)
+	}
+
+	if err := repo.RevokeChannelEditor(channel.ID, editor.ID); err != nil {
+		t.Fatalf("RevokeChannelEditor: %v", err)
+	}
+	if err := repo.RevokeChannelEditor(channel.ID, editor.ID); err != nil {
+		t.Fatalf("repeat RevokeChannelEditor: %v", err)
+	}
+	if repo.IsChannelEditor(channel.ID, editor.ID) {
+		t.Fatal("expected editor grant to be revoked")
+	}
+	editors, err = repo.ListChannelEditors(channel.ID)
+	if err != nil {
+		t.Fatalf("ListChannelEditors after revoke: %v", err)
+	}
+	if len(editors) != 0 {
+		t.Fatalf("expected no editors after revoke, got %+v", editors)
+	}
+}
+
+// RunRepositoryChannelModeratorGrants verifies time-limited moderator and
+// editor grants stop applying the moment they expire, are hidden from
+// listings, and are returned once by the expiry sweep.
+func RunRepositoryChannelModeratorGrants(t *testing.T, factory RepositoryFactory) {
+	now := time.Date(2026, 3, 1, 20, 0, 0, 0, time.UTC)
+	repo := runRepository(t, factory, WithGrantClock(func() time.Time { return now }))
+
+	owner, err := repo.CreateUser(CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	requireAvailable(t, err, "create owner")
+	guest, err := repo.CreateUser(CreateUserParams{DisplayName: "Guest", Email: "guest@example.com"})
+	requireAvailable(t, err, "create guest")
+	editor, err := repo.CreateUser(CreateUserParams{DisplayName: "Editor", Email: "editor@example.com", Roles: []string{"editor"}})
+	requireAvailable(t, err, "create editor")
+	channel, err := repo.CreateChannel(owner.ID, "Studio", "gaming", nil)
+	requireAvailable(t, err, "create channel")
+	subsOnly := true
+	if _, err := repo.UpdateChannel(channel.ID, ChannelUpdate{ChatSubscribersOnly: &subsOnly}); err != nil {
+		t.Fatalf("UpdateChannel: %v", err)
+	}
+
+	tooSoon := now.Add(models.MinChannelGrantDuration - time.Second)
+	if _, err := repo.GrantChannelModerator(channel.ID, guest.ID, owner.ID, &tooSoon); !errors.Is(err, ErrGrantExpiryTooSoon) {
+		t.Fatalf("expected ErrGrantExpiryTooSoon for a short moderator grant, got %v", err)
+	}
+	if _, err := repo.GrantChannelEditor(channel.ID, editor.ID, owner.ID, &tooSoon); !errors.Is(err, ErrGrantExpiryTooSoon) {
+		t.Fatalf("expected ErrGrantExpiryTooSoon for a short editor grant, got %v", err)
+	}
+
+	expiresAt := now.Add(2 * time.Hour)
+	grant, err := repo.GrantChannelModerator(channel.ID, guest.ID, owner.ID, &expiresAt)
+	if err != nil {
+		t.Fatalf("GrantChannelModerator: %v", err)
+	}
+	if grant.GrantedBy != owner.ID || !grant.GrantedAt.Equal(now) || grant.ExpiresAt == nil || !grant.ExpiresAt.Equal(expiresAt) {
+		t.Fatalf("unexpected moderator grant %+v", grant)
+	}
+	if _, err := repo.GrantChannelEditor(channel.ID, editor.ID, owner.ID, &expiresAt); err != nil {
+		t.Fatalf("GrantChannelEditor: %v", err)
+	}
+	if !repo.IsChannelModerator(channel.ID, guest.ID) || !repo.IsChannelEditor(channel.ID, editor.ID) {
+		t.Fatal("expected both grants to apply before expiry")
+	}
+	authors, err := repo.ChatAuthors(channel.ID, []string{guest.ID})
+	if err != nil {
+		t.Fatalf("ChatAuthors: %v", err)
+	}
+	if !authors[guest.ID].ChannelModerator {
+		t.Fatalf("expected the guest to be reported as a channel moderator, got %+v", authors[guest.ID])
+	}
+	if _, err := repo.CreateChatMessage(channel.ID, guest.ID, "hello chat", ""); err != nil {
+		t.Fatalf("expected a channel moderator to chat in subscriber-only mode: %v", err)
+	}
+
+	now = expiresAt
+	if repo.IsChannelModerator(channel.ID, guest.ID) || repo.IsChannelEditor(channel.ID, editor.ID) {
+		t.Fatal("expected both grants to stop applying at expiry, before the sweep")
+	}
+	if moderators, err := repo.ListChannelModerators(channel.ID); err != nil || len(moderators) != 0 {
+		t.Fatalf("expected expired moderators to be hidden, got %+v (err %v)", moderators, err)
+	}
+	if editors, err := repo.ListChannelEditors(channel.ID); err != nil || len(editors) != 0 {
+		t.Fatalf("expected expired editors to be hidden, got %+v (err %v)", editors, err)
+	}
+	if _, err := repo.CreateChatMessage(channel.ID, guest.ID, "still here", ""); !errors.Is(err, chat.ErrSubscribersOnly) {
+		t.Fatalf("expected an expired moderator to be refused in subscriber-only mode, got %v", err)
+	}
+
+	expired, err := repo.PurgeExpiredChannelGrants()
+	if err != nil {
+		t.Fatalf("PurgeExpiredChannelGrants: %v", err)
+	}
+	if len(expired.Moderators) != 1 || expired.Moderators[0].UserID != guest.ID || expired.Moderators[0].ChannelID != channel.ID {
+		t.Fatalf("expected the guest's grant to be swept, got %+v", expired.Moderators)
+	}
+	if len(expired.Editors) != 1 || expired.Editors[0].UserID != editor.ID {
+		t.Fatalf("expected the editor's grant to be swept, got %+v", expired.Editors)
+	}
+	if again, err := repo.PurgeExpiredChannelGrants(); err != nil || len(again.Moderators) != 0 || len(again.Editors) != 0 {
+		t.Fatalf("expected a second sweep to find nothing, got %+v (err %v)", again, err)
+	}
+
+	regrant, err := repo.GrantChannelModerator(channel.ID, guest.ID, "", nil)
+	if err != nil {
+		t.Fatalf("GrantChannelModerator after expiry: %v", err)
+	}
+	if regrant.GrantedBy != "" || !regrant.GrantedAt.Equal(now) || regrant.ExpiresAt != nil {
+		t.Fatalf("expected a fresh permanent grant, got %+v", regrant)
+	}
+	now = now.Add(365 * 24 * time.Hour)
+	if !repo.IsChannelModerator(channel.ID, guest.ID) {
+		t.Fatal("expected a grant without expiry to keep applying")
+	}
+}
+
+// RunRepositoryJobQueue verifies jobs are claimed by one worker at a time,
+// retried after failures, deduplicated by key, and reclaimed once a
+// worker's lease expires.
+func RunRepositoryJobQueue(t *testing.T, factory RepositoryFactory) {
+	repo := runRepository(t, factory)
+
+	if _, err := repo.EnqueueJob(EnqueueJobParams{Type: "  "}); err == nil {
+		t.Fatal("expected error for missing job type")
+	}
+	if _, err := repo.EnqueueJob(EnqueueJobParams{Type: "test.invalid", Payload: []byte("{")}); err == nil {
+		t.Fatal("expected error for invalid payload")
+	}
+
+	const jobCount = 12
+	for i := 0; i < jobCount; i++ {
+		_, err := repo.EnqueueJob(EnqueueJobParams{Type: "test.contention", Payload: []byte(`{"n":1}`)})
+		requireAvailable(t, err, "enqueue job")
+	}
+
+	// Concurrent workers must split the due jobs without overlap.
+	var (
+		mu      sync.Mutex
+		claimed = make(map[string]string)
+		wg      sync.WaitGroup
+	)
+	for w := 0; w < 4; w++ {
+		workerID := "worker-" + string(rune('a'+w))
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				jobs, err := repo.ClaimDueJobs(workerID, 2, time.Minute)
+				if err != nil {
+					t.Errorf("ClaimDueJobs %s: %v", workerID, err)
+					return
+				}
+				if len(jobs) == 0 {
+					return
+				}
+				mu.Lock()
+				for _, job := range jobs {
+					if other, ok := claimed[job.ID]; ok {
+						t.Errorf("job %s claimed by %s and %s", job.ID, other, workerID)
+					}
+					claimed[job.ID] = workerID
+				}
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+	if len(claimed) != jobCount {
+		t.Fatalf("expected %d claimed jobs, got %d", jobCount, len(claimed))
+	}
+	for id, workerID := range claimed {
+		job, ok := repo.GetJob(id)
+		if !ok {
+			t.Fatalf("expected job %s to exist", id)
+		}
+		if job.Status != JobStatusRunning || job.LockedBy != workerID || job.Attempts != 1 {
+			t.Fatalf("unexpected claimed job %+v", job)
+		}
+		if err := repo.CompleteJob(id, "someone-else"); !errors.Is(err, ErrJobNotClaimed) {
+			t.Fatalf("expected ErrJobNotClaimed completing another worker's job, got %v", err)
+		}
+		if err := repo.CompleteJob(id, workerID); err != nil {
+			t.Fatalf("CompleteJob: %v", err)
+		}
+		if _, ok := repo.GetJob(id); ok {
+			t.Fatalf("expected completed job %s to be removed", id)
+		}
+	}
+
+	// Failed jobs wait for their retry time, then fail for good.
+	retried, err := repo.EnqueueJob(EnqueueJobParams{Type: "test.retry"})
+	if err != nil {
+		t.Fatalf("EnqueueJob retry: %v", err)
+	}
+	jobs, err := repo.ClaimDueJobs("retry-worker", 5, time.Minute)
+	if err != nil || len(jobs) != 1 || jobs[0].ID != retried.ID {
+		t.Fatalf("expected to claim retry job, got %+v (err %v)", jobs, err)
+	}
+	failed, err := repo.FailJob(retried.ID, "retry-worker", "boom", time.Now().Add(150*time.Millisecond))
+	if err != nil {
+		t.Fatalf("FailJob: %v", err)
+	}
+	if failed.Status != JobStatusPending || failed.LastError != "boom" || failed.LockedBy != "" {
+		t.Fatalf("unexpected failed job %+v", failed)
+	}
+	if jobs, err := repo.ClaimDueJobs("retry-worker", 5, time.Minute); err != nil || len(jobs) != 0 {
+		t.Fatalf("expected no due jobs before retry time, got %+v (err %v)", jobs, err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	jobs, err = repo.ClaimDueJobs("retry-worker", 5, time.Minute)
+	if err != nil || len(jobs) != 1 || jobs[0].Attempts != 2 {
+		t.Fatalf("expected retried job on second attempt, got %+v (err %v)", jobs, err)
+	}
+	dead, err := repo.FailJob(retried.ID, "retry-worker", "boom again", time.Time{})
+	if err != nil {
+		t.Fatalf("FailJob final: %v", err)
+	}
+	if dead.Status != JobStatusFailed || dead.Attempts != 2 {
+		t.Fatalf("expected job failed after two attempts, got %+v", dead)
+	}
+	if jobs, err := repo.ClaimDueJobs("retry-worker", 5, time.Minute); err != nil || len(jobs) != 0 {
+		t.Fatalf("expected failed job to stay unclaimed, got %+v (err %v)", jobs, err)
+	}
+
+	// Keys deduplicate pending and running jobs only.
+	keyed, err := repo.EnqueueJob(EnqueueJobParams{Type: "test.keyed", Key: "nightly", RunAt: time.Now().Add(time.Hour)})
+	if err != nil {
+		t.Fatalf("EnqueueJob keyed: %v", err)
+	}
+	duplicate, err := repo.EnqueueJob(EnqueueJobParams{Type: "test.keyed", Key: "nightly"})
+	if err != nil {
+		t.Fatalf("EnqueueJob duplicate: %v", err)
+	}
+	if duplicate.ID != keyed.ID {
+		t.Fatalf("expected duplicate key to return job %s, got %s", keyed.ID, duplicate.ID)
+	}
+
+	// A worker that dies mid-job loses it once its lease expires.
+	crashed, err := repo.EnqueueJob(EnqueueJobParams{Type: "test.crash"})
+	if err != nil {
+		t.Fatalf("EnqueueJob crash: %v", err)
 	}
-
-	for _, tc := range cases {
-		t.Run(tc.name, func(t *testing.T) {
-			channels := repo.ListChannels(tc.ownerID, tc.query)
-			if len(channels) != len(tc.wantIDs) {
-				t.Fatalf("expected %d channels, got %d", len(tc.wantIDs), len(channels))
-			}
-			for i, id := range tc.wantIDs {
-				if channels[i].ID != id {
-					t.Fatalf("expected channel %s at index %d, got %s", id, i, channels[i].ID)
-				}
-			}
-		})
+	jobs, err = repo.ClaimDueJobs("doomed-worker", 5, 100*time.Millisecond)
+	if err != nil || len(jobs) != 1 || jobs[0].ID != crashed.ID {
+		t.Fatalf("expected to claim crash job, got %+v (err %v)", jobs, err)
+	}
+	if jobs, err := repo.ClaimDueJobs("rescue-worker", 5, time.Minute); err != nil || len(jobs) != 0 {
+		t.Fatalf("expected leased job to stay claimed, got %+v (err %v)", jobs, err)
+	}
+	time.Sleep(200 * time.Millisecond)
+	jobs, err = repo.ClaimDueJobs("rescue-worker", 5, time.Minute)
+	if err != nil || len(jobs) != 1 || jobs[0].ID != crashed.ID || jobs[0].Attempts != 2 {
+		t.Fatalf("expected rescue worker to reclaim job, got %+v (err %v)", jobs, err)
+	}
+	if _, err := repo.FailJob(crashed.ID, "doomed-worker", "late", time.Time{}); !errors.Is(err, ErrJobNotClaimed) {
+		t.Fatalf("expected ErrJobNotClaimed for expired lease, got %v", err)
+	}
+	if err := repo.CompleteJob(crashed.ID, "rescue-worker"); err != nil {
+		t.Fatalf("CompleteJob rescued: %v", err)
+	}
+	if err := repo.CompleteJob("missing", "rescue-worker"); err == nil || errors.Is(err, ErrJobNotClaimed) {
+		t.Fatalf("expected not found error for missing job, got %v", err)
 	}
 }
 
-// RunRepositoryChannelLookupByStreamKey ensures repositories can resolve channels from stream keys.
-func RunRepositoryChannelLookupByStreamKey(t *testing.T, factory RepositoryFactory) {
+// RunRepositoryChannelFollowers verifies followers are listed newest first,
+// paged, and counted, and that deleted users drop out of the listing.
+func RunRepositoryChannelFollowers(t *testing.T, factory RepositoryFactory) {
 	repo := runRepository(t, factory)
 
-	owner, err := repo.CreateUser(CreateUserParams{DisplayName: "Owner", Email: "owner@example.com"})
+	owner, err := repo.CreateUser(CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
 	requireAvailable(t, err, "create owner")
-	channel, err := repo.CreateChannel(owner.ID, "Live", "gaming", []string{"rpg"})
+	channel, err := repo.CreateChannel(owner.ID, "Studio", "gaming", nil)
 	requireAvailable(t, err, "create channel")
 
-	fetched, ok := repo.GetChannelByStreamKey(channel.StreamKey)
-	if !ok {
-		t.Fatal("expected channel to be found by stream key")
+	empty, err := repo.ListChannelFollowers(channel.ID, FollowerListOptions{})
+	if err != nil {
+		t.Fatalf("ListChannelFollowers empty: %v", err)
 	}
-	if fetched.ID != channel.ID {
-		t.Fatalf("expected channel %s, got %s", channel.ID, fetched.ID)
+	if empty.Total != 0 || len(empty.Followers) != 0 {
+		t.Fatalf("expected no followers, got %+v", empty)
 	}
 
-	if _, ok := repo.GetChannelByStreamKey("missing-key"); ok {
-		t.Fatal("expected missing stream key to return ok=false")
+	var followers []models.User
+	for _, name := range []string{"First", "Second", "Third", "Fourth"} {
+		user, err := repo.CreateUser(CreateUserParams{DisplayName: name, Email: strings.ToLower(name) + "@example.com"})
+		requireAvailable(t, err, "create follower")
+		if err := repo.FollowChannel(user.ID, channel.ID); err != nil {
+			t.Fatalf("FollowChannel %s: %v", name, err)
+		}
+		followers = append(followers, user)
+		time.Sleep(2 * time.Millisecond)
+	}
+	avatar := "https://cdn.example.com/third.png"
+	if _, err := repo.UpsertProfile(followers[2].ID, ProfileUpdate{AvatarURL: &avatar}); err != nil {
+		t.Fatalf("UpsertProfile: %v", err)
+	}
+
+	first, err := repo.ListChannelFollowers(channel.ID, FollowerListOptions{Limit: 2})
+	if err != nil {
+		t.Fatalf("ListChannelFollowers page 1: %v", err)
+	}
+	if first.Total != 4 || len(first.Followers) != 2 {
+		t.Fatalf("expected 2 of 4 followers, got %d of %d", len(first.Followers), first.Total)
+	}
+	if first.Followers[0].UserID != followers[3].ID || first.Followers[1].UserID != followers[2].ID {
+		t.Fatalf("expected newest followers first, got %+v", first.Followers)
+	}
+	if first.Followers[1].DisplayName != "Third" || first.Followers[1].AvatarURL != avatar {
+		t.Fatalf("expected follower summary with avatar, got %+v", first.Followers[1])
+	}
+	if first.Followers[0].FollowedAt.Before(first.Followers[1].FollowedAt) || first.Followers[1].FollowedAt.IsZero() {
+		t.Fatalf("expected descending followedAt, got %+v", first.Followers)
+	}
+
+	second, err := repo.ListChannelFollowers(channel.ID, FollowerListOptions{Limit: 2, Offset: 2})
+	if err != nil {
+		t.Fatalf("ListChannelFollowers page 2: %v", err)
+	}
+	if len(second.Followers) != 2 || second.Followers[0].UserID != followers[1].ID || second.Followers[1].UserID != followers[0].ID {
+		t.Fatalf("unexpected second page %+v", second.Followers)
+	}
+
+	if err := repo.DeleteUser(followers[3].ID); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+	if err := repo.UnfollowChannel(followers[0].ID, channel.ID); err != nil {
+		t.Fatalf("UnfollowChannel: %v", err)
+	}
+	remaining, err := repo.ListChannelFollowers(channel.ID, FollowerListOptions{})
+	if err != nil {
+		t.Fatalf("ListChannelFollowers after removals: %v", err)
+	}
+	if remaining.Total != 2 || len(remaining.Followers) != 2 || remaining.Followers[0].UserID != followers[2].ID {
+		t.Fatalf("expected deleted and unfollowed users to drop out, got %+v", remaining)
 	}
 }
 
@@ -508,6 +1449,77 @@ func RunRepositoryChatReportsLifecycle(t *testing.T, factory RepositoryFactory)
 	}
 }
 
+// RunRepositoryChatMessagesInRange asserts that chat replay windows return
+// only messages inside the window, oldest first, and page with a cursor.
+func RunRepositoryChatMessagesInRange(t *testing.T, factory RepositoryFactory) {
+	repo := runRepository(t, factory)
+
+	owner, err := repo.CreateUser(CreateUserParams{DisplayName: "owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	requireAvailable(t, err, "create owner")
+	viewer, err := repo.CreateUser(CreateUserParams{DisplayName: "viewer", Email: "viewer@example.com"})
+	requireAvailable(t, err, "create viewer")
+	channel, err := repo.CreateChannel(owner.ID, "Lobby", "gaming", nil)
+	requireAvailable(t, err, "create channel")
+	other, err := repo.CreateChannel(owner.ID, "Other", "gaming", nil)
+	requireAvailable(t, err, "create other channel")
+
+	start := time.Date(2024, 3, 1, 20, 0, 0, 0, time.UTC)
+	seed := []struct {
+		id        string
+		channelID string
+		offset    time.Duration
+	}{
+		{"msg-before", channel.ID, -time.Second},
+		{"msg-b", channel.ID, 10 * time.Second},
+		{"msg-a", channel.ID, 10 * time.Second},
+		{"msg-c", channel.ID, 30 * time.Second},
+		{"msg-end", channel.ID, time.Minute},
+		{"msg-other", other.ID, 20 * time.Second},
+	}
+	for _, msg := range seed {
+		err := repo.ApplyChatEvent(chat.Event{
+			Type: chat.EventTypeMessage,
+			Message: &chat.MessageEvent{
+				ID:        msg.id,
+				ChannelID: msg.channelID,
+				UserID:    viewer.ID,
+				Content:   msg.id,
+				CreatedAt: start.Add(msg.offset),
+			},
+		})
+		requireAvailable(t, err, "seed chat message")
+	}
+
+	window := ChatMessageRangeParams{ChannelID: channel.ID, Start: start, End: start.Add(time.Minute)}
+	messages, err := repo.ListChatMessagesInRange(window)
+	requireAvailable(t, err, "list chat window")
+	ids := make([]string, 0, len(messages))
+	for _, msg := range messages {
+		ids = append(ids, msg.ID)
+	}
+	if want := []string{"msg-a", "msg-b", "msg-c"}; !reflect.DeepEqual(ids, want) {
+		t.Fatalf("expected window messages %v, got %v", want, ids)
+	}
+
+	window.Limit = 1
+	first, err := repo.ListChatMessagesInRange(window)
+	requireAvailable(t, err, "list first page")
+	if len(first) != 1 || first[0].ID != "msg-a" {
+		t.Fatalf("expected first page to contain msg-a, got %+v", first)
+	}
+	window.AfterCreatedAt = first[0].CreatedAt
+	window.AfterID = first[0].ID
+	second, err := repo.ListChatMessagesInRange(window)
+	requireAvailable(t, err, "list second page")
+	if len(second) != 1 || second[0].ID != "msg-b" {
+		t.Fatalf("expected second page to contain msg-b, got %+v", second)
+	}
+
+	if _, err := repo.ListChatMessagesInRange(ChatMessageRangeParams{ChannelID: channel.ID, Start: start, End: start}); err == nil {
+		t.Fatal("expected empty window to be rejected")
+	}
+}
+
 // RunRepositoryTipsLifecycle asserts tip creation and listing behaviour against
 // a repository implementation.
 func RunRepositoryTipsLifecycle(t *testing.T, factory RepositoryFactory) {
@@ -653,6 +1665,126 @@ func RunRepositorySubscriptionsLifecycle(t *testing.T, factory RepositoryFactory
 	}
 }
 
+// RunRepositorySubscriptionGifts verifies gifted subscriptions are created or
+// extended for every recipient atomically.
+func RunRepositorySubscriptionGifts(t *testing.T, factory RepositoryFactory) {
+	repo := runRepository(t, factory)
+
+	owner, err := repo.CreateUser(CreateUserParams{DisplayName: "owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	requireAvailable(t, err, "create owner")
+	gifter, err := repo.CreateUser(CreateUserParams{DisplayName: "gifter", Email: "gifter@example.com"})
+	requireAvailable(t, err, "create gifter")
+	first, err := repo.CreateUser(CreateUserParams{DisplayName: "first", Email: "first@example.com"})
+	requireAvailable(t, err, "create first recipient")
+	second, err := repo.CreateUser(CreateUserParams{DisplayName: "second", Email: "second@example.com"})
+	requireAvailable(t, err, "create second recipient")
+	existingSubscriber, err := repo.CreateUser(CreateUserParams{DisplayName: "subscriber", Email: "subscriber@example.com"})
+	requireAvailable(t, err, "create subscriber")
+	channel, err := repo.CreateChannel(owner.ID, "Lobby", "gaming", nil)
+	requireAvailable(t, err, "create channel")
+
+	existing, err := repo.CreateSubscription(CreateSubscriptionParams{
+		ChannelID: channel.ID,
+		UserID:    existingSubscriber.ID,
+		Tier:      "tier1",
+		Provider:  "stripe",
+		Reference: "sub-existing",
+		Amount:    models.MustParseMoney("4.99"),
+		Currency:  "usd",
+		Duration:  48 * time.Hour,
+	})
+	requireAvailable(t, err, "create existing subscription")
+	existing, ok := repo.GetSubscription(existing.ID)
+	if !ok {
+		t.Fatalf("expected GetSubscription to find %q", existing.ID)
+	}
+
+	gifts, err := repo.GiftSubscriptions(GiftSubscriptionsParams{
+		ChannelID:    channel.ID,
+		GifterID:     gifter.ID,
+		RecipientIDs: []string{first.ID, second.ID, existingSubscriber.ID},
+		Tier:         "tier1",
+		Provider:     "stripe",
+		Reference:    "gift-batch",
+		Amount:       models.MustParseMoney("4.99"),
+		Currency:     "usd",
+		Duration:     24 * time.Hour,
+	})
+	requireAvailable(t, err, "gift subscriptions")
+	if len(gifts) != 3 {
+		t.Fatalf("expected 3 gifted subscriptions, got %d", len(gifts))
+	}
+	references := make(map[string]struct{})
+	for _, gift := range gifts[:2] {
+		if !gift.IsGift || gift.GifterID != gifter.ID {
+			t.Fatalf("expected gift to credit gifter %s, got %+v", gifter.ID, gift)
+		}
+		if _, dup := references[gift.Reference]; dup {
+			t.Fatalf("expected distinct references, got duplicate %q", gift.Reference)
+		}
+		references[gift.Reference] = struct{}{}
+		stored, ok := repo.GetSubscription(gift.ID)
+		if !ok || stored.GifterID != gifter.ID || !stored.IsGift {
+			t.Fatalf("expected stored gift to persist gifter, got %+v", stored)
+		}
+	}
+	if gifts[0].Reference != "gift-batch-1" || gifts[1].Reference != "gift-batch-2" {
+		t.Fatalf("expected references derived from the batch reference, got %q and %q", gifts[0].Reference, gifts[1].Reference)
+	}
+
+	extended := gifts[2]
+	if extended.ID != existing.ID {
+		t.Fatalf("expected existing subscription %s to be extended, got %s", existing.ID, extended.ID)
+	}
+	if want := existing.ExpiresAt.Add(24 * time.Hour); !extended.ExpiresAt.Equal(want) {
+		t.Fatalf("expected expiry extended to %s, got %s", want, extended.ExpiresAt)
+	}
+	stored, ok := repo.GetSubscription(existing.ID)
+	if !ok || !stored.ExpiresAt.Equal(extended.ExpiresAt) {
+		t.Fatalf("expected extended expiry to persist, got %+v", stored)
+	}
+	subs, err := repo.ListSubscriptions(channel.ID, true)
+	requireAvailable(t, err, "list subscriptions")
+	if len(subs) != 3 {
+		t.Fatalf("expected extension instead of a duplicate row, got %d subscriptions", len(subs))
+	}
+
+	_, err = repo.GiftSubscriptions(GiftSubscriptionsParams{
+		ChannelID:    channel.ID,
+		GifterID:     gifter.ID,
+		RecipientIDs: []string{first.ID, "missing-user"},
+		Tier:         "tier1",
+		Provider:     "stripe",
+		Reference:    "gift-partial",
+		Amount:       models.MustParseMoney("4.99"),
+		Currency:     "usd",
+		Duration:     24 * time.Hour,
+	})
+	if err == nil {
+		t.Fatal("expected gift with unknown recipient to fail")
+	}
+	after, err := repo.ListSubscriptions(channel.ID, true)
+	requireAvailable(t, err, "list subscriptions after failed gift")
+	if len(after) != len(subs) {
+		t.Fatalf("expected failed gift to leave %d subscriptions, got %d", len(subs), len(after))
+	}
+	reloaded, ok := repo.GetSubscription(gifts[0].ID)
+	if !ok || !reloaded.ExpiresAt.Equal(gifts[0].ExpiresAt) {
+		t.Fatalf("expected failed gift not to extend existing subscriptions, got %+v", reloaded)
+	}
+
+	if _, err := repo.GiftSubscriptions(GiftSubscriptionsParams{
+		ChannelID:    channel.ID,
+		GifterID:     gifter.ID,
+		RecipientIDs: []string{gifter.ID},
+		Provider:     "stripe",
+		Currency:     "usd",
+		Duration:     time.Hour,
+	}); err == nil {
+		t.Fatal("expected self-gift to fail")
+	}
+}
+
 // RunRepositoryMonetizationPrecision verifies repositories preserve fixed-precision
 // minor units for tips and subscriptions.
 func RunRepositoryMonetizationPrecision(t *testing.T, factory RepositoryFactory) {
@@ -865,6 +1997,103 @@ func RunRepositoryRecordingRetention(t *testing.T, factory RepositoryFactory) {
 	}
 }
 
+// RunRepositoryRecordingPolicy verifies each channel recording policy's
+// effect on StopStream and that auto-published recordings get the published
+// retention window.
+func RunRepositoryRecordingPolicy(t *testing.T, factory RepositoryFactory) {
+	policy := RecordingRetentionPolicy{Published: 30 * 24 * time.Hour, Unpublished: 48 * time.Hour}
+	controller := &fakeIngestController{bootDefault: ingest.BootResult{
+		Renditions: []ingest.Rendition{{Name: "720p", ManifestURL: "https://origin/720p.m3u8"}},
+	}}
+	repo := runRepository(t, factory, WithRecordingRetention(policy), WithIngestController(controller))
+
+	owner, err := repo.CreateUser(CreateUserParams{DisplayName: "owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	requireAvailable(t, err, "create owner")
+
+	channel, err := repo.CreateChannel(owner.ID, "Policy", "gaming", nil)
+	requireAvailable(t, err, "create channel")
+	if channel.RecordingPolicy != models.RecordingPolicyManual {
+		t.Fatalf("expected new channel to default to manual, got %q", channel.RecordingPolicy)
+	}
+	invalid := "archive"
+	if _, err := repo.UpdateChannel(channel.ID, ChannelUpdate{RecordingPolicy: &invalid}); err == nil {
+		t.Fatal("expected invalid recording policy to be rejected")
+	}
+
+	cases := []struct {
+		policy    string
+		recorded  bool
+		published bool
+		window    time.Duration
+	}{
+		{policy: models.RecordingPolicyManual, recorded: true, window: policy.Unpublished},
+		{policy: models.RecordingPolicyAutoPublish, recorded: true, published: true, window: policy.Published},
+		{policy: models.RecordingPolicyDiscard},
+	}
+	for _, tc := range cases {
+		value := strings.ToUpper(tc.policy)
+		updated, err := repo.UpdateChannel(channel.ID, ChannelUpdate{RecordingPolicy: &value})
+		if err != nil {
+			t.Fatalf("UpdateChannel %s: %v", tc.policy, err)
+		}
+		if fetched, ok := repo.GetChannel(channel.ID); !ok || updated.RecordingPolicy != tc.policy || fetched.RecordingPolicy != tc.policy {
+			t.Fatalf("expected recording policy %s to persist, got %q / %q", tc.policy, updated.RecordingPolicy, fetched.RecordingPolicy)
+		}
+
+		before, err := repo.ListRecordings(channel.ID, true)
+		requireAvailable(t, err, "list recordings before stop")
+		_, err = repo.StartStream(channel.ID, []string{"720p"})
+		requireAvailable(t, err, "start stream")
+		session, err := repo.StopStream(channel.ID, 0)
+		requireAvailable(t, err, "stop stream")
+		if session.EndedAt == nil {
+			t.Fatalf("%s: expected session to end", tc.policy)
+		}
+		if state, _ := repo.GetChannel(channel.ID); state.LiveState != "offline" || state.CurrentSessionID != nil {
+			t.Fatalf("%s: expected channel offline after stop, got %+v", tc.policy, state)
+		}
+
+		after, err := repo.ListRecordings(channel.ID, true)
+		requireAvailable(t, err, "list recordings after stop")
+		if !tc.recorded {
+			if len(after) != len(before) {
+				t.Fatalf("%s: expected no new recording, got %d -> %d", tc.policy, len(before), len(after))
+			}
+			continue
+		}
+		if len(after) != len(before)+1 {
+			t.Fatalf("%s: expected one new recording, got %d -> %d", tc.policy, len(before), len(after))
+		}
+		var recording models.Recording
+		for _, candidate := range after {
+			if candidate.SessionID == session.ID {
+				recording = candidate
+			}
+		}
+		if recording.ID == "" {
+			t.Fatalf("%s: recording for session %s not found", tc.policy, session.ID)
+		}
+		if (recording.PublishedAt != nil) != tc.published {
+			t.Fatalf("%s: expected published=%v, got %v", tc.policy, tc.published, recording.PublishedAt)
+		}
+		if recording.RetainUntil == nil {
+			t.Fatalf("%s: expected a retention deadline", tc.policy)
+		}
+		if got := recording.RetainUntil.Sub(*session.EndedAt); got < tc.window-time.Minute || got > tc.window+time.Minute {
+			t.Fatalf("%s: expected retention window %v, got %v", tc.policy, tc.window, got)
+		}
+		published, err := repo.ListRecordings(channel.ID, false)
+		requireAvailable(t, err, "list published recordings")
+		visible := false
+		for _, candidate := range published {
+			visible = visible || candidate.ID == recording.ID
+		}
+		if visible != tc.published {
+			t.Fatalf("%s: expected public visibility %v, got %v", tc.policy, tc.published, visible)
+		}
+	}
+}
+
 func RunRepositoryRecordingRetentionFailures(t *testing.T, factory RepositoryFactory) {
 	policy := RecordingRetentionPolicy{Published: 0, Unpublished: 0}
 	retentionNow := time.Now().UTC().Add(-1 * time.Hour)
@@ -1141,6 +2370,299 @@ func RunRepositoryStreamTimeouts(t *testing.T, factory RepositoryFactory) {
 	}
 }
 
+// RunRepositoryStreamSettingsSnapshot verifies sessions keep the settings
+// they started with and hand them on to the recording.
+func RunRepositoryStreamSettingsSnapshot(t *testing.T, factory RepositoryFactory) {
+	controller := &timeoutIngestController{bootResult: ingest.BootResult{
+		PrimaryIngest: "rtmp://ingest.example/live",
+		BackupIngest:  "rtmp://backup.example/live",
+		OriginURL:     "https://origin.example/live",
+		PlaybackURL:   "wss://playback.example/live",
+		Renditions: []ingest.Rendition{
+			{Name: "1080p", ManifestURL: "https://cdn.example/1080p.m3u8", Bitrate: 6000},
+			{Name: "source", ManifestURL: "https://cdn.example/source.m3u8"},
+		},
+		PreviewURL: "https://cdn.example/live/job-1/preview.jpg",
+	}}
+	repo := runRepository(t, factory, WithIngestController(controller))
+
+	owner, err := repo.CreateUser(CreateUserParams{DisplayName: "Creator", Email: "settings@example.com", Roles: []string{"creator"}})
+	requireAvailable(t, err, "create owner")
+	channel, err := repo.CreateChannel(owner.ID, "Settings", "gaming", nil)
+	requireAvailable(t, err, "create channel")
+
+	session, err := repo.StartStream(channel.ID, []string{"1080p", "720p"})
+	requireAvailable(t, err, "start stream")
+	want := &models.StreamSettings{
+		RequestedRenditions: []string{"1080p", "720p"},
+		Ladder:              []models.LadderRendition{{Name: "1080p", Bitrate: 6000}, {Name: "source"}},
+		LatencyMode:         models.LatencyModeUltraLow,
+	}
+	if !reflect.DeepEqual(session.Settings, want) {
+		t.Fatalf("expected settings %+v, got %+v", want, session.Settings)
+	}
+	current, ok := repo.CurrentStreamSession(channel.ID)
+	if !ok {
+		t.Fatal("expected current session")
+	}
+	if !reflect.DeepEqual(current.Settings, want) {
+		t.Fatalf("expected current session settings %+v, got %+v", want, current.Settings)
+	}
+	if current.PreviewURL != controller.bootResult.PreviewURL {
+		t.Fatalf("expected current session preview URL %q, got %q", controller.bootResult.PreviewURL, current.PreviewURL)
+	}
+
+	ended, err := repo.StopStream(channel.ID, 4)
+	requireAvailable(t, err, "stop stream")
+	if !reflect.DeepEqual(ended.Settings, want) {
+		t.Fatalf("expected ended session settings %+v, got %+v", want, ended.Settings)
+	}
+	sessions, err := repo.ListStreamSessions(channel.ID)
+	requireAvailable(t, err, "list sessions")
+	if len(sessions) != 1 || !reflect.DeepEqual(sessions[0].Settings, want) || sessions[0].PreviewURL != controller.bootResult.PreviewURL {
+		t.Fatalf("expected listed session to keep settings and preview URL, got %+v", sessions)
+	}
+
+	recordings, err := repo.ListRecordings(channel.ID, true)
+	requireAvailable(t, err, "list recordings")
+	if len(recordings) != 1 {
+		t.Fatalf("expected one recording, got %d", len(recordings))
+	}
+	metadata := recordings[0].Metadata
+	if metadata["latencyMode"] != models.LatencyModeUltraLow {
+		t.Fatalf("expected latency mode in recording metadata, got %+v", metadata)
+	}
+	if metadata["requestedRenditions"] != "1080p,720p" {
+		t.Fatalf("expected requested renditions in recording metadata, got %+v", metadata)
+	}
+	if metadata["ladder"] != "1080p@6000,source" {
+		t.Fatalf("expected ladder in recording metadata, got %+v", metadata)
+	}
+}
+
+// RunRepositoryStreamPlaybackEndpoints verifies sessions keep every playback
+// endpoint the origin reported, in order, alongside the legacy playback URL.
+func RunRepositoryStreamPlaybackEndpoints(t *testing.T, factory RepositoryFactory) {
+	endpoints := []models.PlaybackEndpoint{
+		{Protocol: models.PlaybackProtocolWebRTC, URL: "wss://edge.example/live/channel", Priority: 1},
+		{Protocol: models.PlaybackProtocolLLHLS, URL: "https://edge.example/live/channel/llhls.m3u8", Priority: 2},
+		{Protocol: models.PlaybackProtocolHLS, URL: "https://edge.example/live/channel/playlist.m3u8", Priority: 3},
+	}
+	controller := &timeoutIngestController{bootResult: ingest.BootResult{
+		OriginURL:         "https://origin.example/live",
+		PlaybackURL:       "https://edge.example/live/channel/llhls.m3u8",
+		PlaybackEndpoints: endpoints,
+		Renditions:        []ingest.Rendition{{Name: "source", ManifestURL: "https://edge.example/live/channel/source.m3u8"}},
+	}}
+	repo := runRepository(t, factory, WithIngestController(controller))
+
+	owner, err := repo.CreateUser(CreateUserParams{DisplayName: "Creator", Email: "endpoints@example.com", Roles: []string{"creator"}})
+	requireAvailable(t, err, "create owner")
+	channel, err := repo.CreateChannel(owner.ID, "Endpoints", "gaming", nil)
+	requireAvailable(t, err, "create channel")
+
+	session, err := repo.StartStream(channel.ID, []string{"source"})
+	requireAvailable(t, err, "start stream")
+	if !reflect.DeepEqual(session.PlaybackEndpoints, endpoints) {
+		t.Fatalf("expected endpoints %+v, got %+v", endpoints, session.PlaybackEndpoints)
+	}
+	if session.PlaybackURL != controller.bootResult.PlaybackURL {
+		t.Fatalf("expected legacy playback URL %q, got %q", controller.bootResult.PlaybackURL, session.PlaybackURL)
+	}
+	if session.Settings == nil || session.Settings.LatencyMode != models.LatencyModeUltraLow {
+		t.Fatalf("expected a WebRTC-first session to be ultra-low latency, got %+v", session.Settings)
+	}
+	current, ok := repo.CurrentStreamSession(channel.ID)
+	if !ok {
+		t.Fatal("expected current session")
+	}
+	if !reflect.DeepEqual(current.PlaybackEndpoints, endpoints) || current.PlaybackURL != controller.bootResult.PlaybackURL {
+		t.Fatalf("expected current session to keep endpoints, got %+v", current)
+	}
+
+	ended, err := repo.StopStream(channel.ID, 2)
+	requireAvailable(t, err, "stop stream")
+	if !reflect.DeepEqual(ended.PlaybackEndpoints, endpoints) {
+		t.Fatalf("expected ended session endpoints %+v, got %+v", endpoints, ended.PlaybackEndpoints)
+	}
+	sessions, err := repo.ListStreamSessions(channel.ID)
+	requireAvailable(t, err, "list sessions")
+	if len(sessions) != 1 || !reflect.DeepEqual(sessions[0].PlaybackEndpoints, endpoints) {
+		t.Fatalf("expected listed session to keep endpoints, got %+v", sessions)
+	}
+}
+
+// clipIngestController cuts live clips from the jobs it booted, recording the
+// durations it was asked for.
+type clipIngestController struct {
+	timeoutIngestController
+	clipErr   error
+	durations []int
+}
+
+func (c *clipIngestController) CaptureClip(ctx context.Context, jobID string, durationSeconds int) (ingest.LiveClip, error) {
+	if c.clipErr != nil {
+		return ingest.LiveClip{}, c.clipErr
+	}
+	c.durations = append(c.durations, durationSeconds)
+	id := fmt.Sprintf("clip-%d", len(c.durations))
+	return ingest.LiveClip{ID: id, PlaybackURL: "https://cdn.example/clips/" + id + ".mp4", DurationSeconds: durationSeconds}, nil
+}
+
+// RunRepositoryLiveClips verifies that viewers can clip a live session, that
+// clips are attributed to their creator, and that StopStream links them to
+// the session's recording.
+func RunRepositoryLiveClips(t *testing.T, factory RepositoryFactory) {
+	controller := &clipIngestController{timeoutIngestController: timeoutIngestController{bootResult: ingest.BootResult{
+		PrimaryIngest: "rtmp://ingest.example/live",
+		OriginURL:     "https://origin.example/live",
+		PlaybackURL:   "https://playback.example/live.m3u8",
+		JobIDs:        []string{"job-1"},
+	}}}
+	repo := runRepository(t, factory, WithIngestController(controller))
+
+	owner, err := repo.CreateUser(CreateUserParams{DisplayName: "Creator", Email: "clips-owner@example.com", Roles: []string{"creator"}})
+	requireAvailable(t, err, "create owner")
+	viewer, err := repo.CreateUser(CreateUserParams{DisplayName: "Viewer", Email: "clips-viewer@example.com"})
+	requireAvailable(t, err, "create viewer")
+	channel, err := repo.CreateChannel(owner.ID, "Clippable", "gaming", nil)
+	requireAvailable(t, err, "create channel")
+
+	if _, err := repo.CreateLiveClip(channel.ID, LiveClipParams{UserID: viewer.ID, Title: "Too early"}); !errors.Is(err, ErrChannelNotLive) {
+		t.Fatalf("expected ErrChannelNotLive while offline, got %v", err)
+	}
+
+	session, err := repo.StartStream(channel.ID, []string{"720p"})
+	requireAvailable(t, err, "start stream")
+
+	if _, err := repo.CreateLiveClip(channel.ID, LiveClipParams{UserID: viewer.ID, Title: "Too long", DurationSeconds: MaxLiveClipSeconds + 1}); err == nil {
+		t.Fatal("expected clips longer than the maximum to be rejected")
+	}
+	if _, err := repo.CreateLiveClip(channel.ID, LiveClipParams{UserID: viewer.ID}); err == nil {
+		t.Fatal("expected a title to be required")
+	}
+
+	clip, err := repo.CreateLiveClip(channel.ID, LiveClipParams{UserID: viewer.ID, Title: " Big play "})
+	if err != nil {
+		t.Fatalf("CreateLiveClip: %v", err)
+	}
+	if clip.RecordingID != "" {
+		t.Fatalf("expected live clip to have no recording yet, got %q", clip.RecordingID)
+	}
+	if clip.SessionID != session.ID || clip.ChannelID != channel.ID {
+		t.Fatalf("expected clip against session %s, got %+v", session.ID, clip)
+	}
+	if clip.CreatedBy != viewer.ID {
+		t.Fatalf("expected clip attributed to %s, got %q", viewer.ID, clip.CreatedBy)
+	}
+	if clip.Title != "Big play" || clip.Status != "ready" || clip.PlaybackURL != "https://cdn.example/clips/clip-1.mp4" {
+		t.Fatalf("unexpected clip: %+v", clip)
+	}
+	if clip.EndSeconds-clip.StartSeconds != DefaultLiveClipSeconds || clip.StartSeconds < 0 {
+		t.Fatalf("expected a %ds clip, got %d-%d", DefaultLiveClipSeconds, clip.StartSeconds, clip.EndSeconds)
+	}
+	if len(controller.durations) != 1 || controller.durations[0] != DefaultLiveClipSeconds {
+		t.Fatalf("expected the default duration to be requested, got %v", controller.durations)
+	}
+
+	controller.clipErr = ingest.ErrClipUnavailable
+	if _, err := repo.CreateLiveClip(channel.ID, LiveClipParams{UserID: viewer.ID, Title: "Nothing buffered"}); !errors.Is(err, ingest.ErrClipUnavailable) {
+		t.Fatalf("expected ErrClipUnavailable, got %v", err)
+	}
+	controller.clipErr = nil
+
+	if _, err := repo.StopStream(channel.ID, 3); err != nil {
+		t.Fatalf("StopStream: %v", err)
+	}
+	recordings, err := repo.ListRecordings(channel.ID, true)
+	requireAvailable(t, err, "list recordings")
+	if len(recordings) != 1 {
+		t.Fatalf("expected one recording, got %d", len(recordings))
+	}
+	recording := recordings[0]
+	clips, err := repo.ListClipExports(recording.ID)
+	requireAvailable(t, err, "list clips")
+	if len(clips) != 1 || clips[0].ID != clip.ID {
+		t.Fatalf("expected the live clip to be linked to the recording, got %+v", clips)
+	}
+	if clips[0].RecordingID != recording.ID || clips[0].CreatedBy != viewer.ID {
+		t.Fatalf("expected linked clip to keep its creator, got %+v", clips[0])
+	}
+	fetched, ok := repo.GetRecording(recording.ID)
+	if !ok || len(fetched.Clips) != 1 || fetched.Clips[0].ID != clip.ID {
+		t.Fatalf("expected recording to list the live clip, got %+v", fetched.Clips)
+	}
+}
+
+// RunRepositoryStreamMarkers verifies that markers are only accepted while a
+// session is live, that offsets count from the session start, that sessions
+// are capped at MaxStreamMarkersPerSession, and that markers outlive the
+// stream.
+func RunRepositoryStreamMarkers(t *testing.T, factory RepositoryFactory) {
+	controller := &timeoutIngestController{bootResult: ingest.BootResult{
+		PrimaryIngest: "rtmp://ingest.example/live",
+		OriginURL:     "https://origin.example/live",
+		PlaybackURL:   "https://playback.example/live.m3u8",
+		JobIDs:        []string{"job-1"},
+	}}
+	repo := runRepository(t, factory, WithIngestController(controller))
+
+	owner, err := repo.CreateUser(CreateUserParams{DisplayName: "Creator", Email: "markers-owner@example.com", Roles: []string{"creator"}})
+	requireAvailable(t, err, "create owner")
+	channel, err := repo.CreateChannel(owner.ID, "Marked", "gaming", nil)
+	requireAvailable(t, err, "create channel")
+
+	if _, err := repo.CreateStreamMarker(channel.ID, StreamMarkerParams{UserID: owner.ID, Label: "Too early"}); !errors.Is(err, ErrChannelNotLive) {
+		t.Fatalf("expected ErrChannelNotLive while offline, got %v", err)
+	}
+
+	session, err := repo.StartStream(channel.ID, []string{"720p"})
+	requireAvailable(t, err, "start stream")
+
+	if _, err := repo.CreateStreamMarker(channel.ID, StreamMarkerParams{UserID: owner.ID, Label: "  "}); err == nil {
+		t.Fatal("expected a label to be required")
+	}
+
+	late, err := repo.CreateStreamMarker(channel.ID, StreamMarkerParams{UserID: owner.ID, Label: " Boss fight ", At: session.StartedAt.Add(90*time.Second + 700*time.Millisecond)})
+	if err != nil {
+		t.Fatalf("CreateStreamMarker: %v", err)
+	}
+	if late.OffsetSeconds != 90 || late.Label != "Boss fight" || late.SessionID != session.ID || late.ChannelID != channel.ID || late.CreatedBy != owner.ID {
+		t.Fatalf("unexpected marker: %+v", late)
+	}
+	early, err := repo.CreateStreamMarker(channel.ID, StreamMarkerParams{UserID: owner.ID, Label: "Intro", At: session.StartedAt.Add(5 * time.Second)})
+	if err != nil {
+		t.Fatalf("CreateStreamMarker early: %v", err)
+	}
+
+	markers, err := repo.ListStreamMarkers(session.ID)
+	requireAvailable(t, err, "list markers")
+	if len(markers) != 2 || markers[0].ID != early.ID || markers[1].ID != late.ID {
+		t.Fatalf("expected markers ordered by offset, got %+v", markers)
+	}
+
+	for i := len(markers); i < MaxStreamMarkersPerSession; i++ {
+		if _, err := repo.CreateStreamMarker(channel.ID, StreamMarkerParams{UserID: owner.ID, Label: fmt.Sprintf("Marker %d", i)}); err != nil {
+			t.Fatalf("CreateStreamMarker %d: %v", i, err)
+		}
+	}
+	if _, err := repo.CreateStreamMarker(channel.ID, StreamMarkerParams{UserID: owner.ID, Label: "One too many"}); !errors.Is(err, ErrStreamMarkerLimit) {
+		t.Fatalf("expected ErrStreamMarkerLimit, got %v", err)
+	}
+
+	if _, err := repo.StopStream(channel.ID, 1); err != nil {
+		t.Fatalf("StopStream: %v", err)
+	}
+	if _, err := repo.CreateStreamMarker(channel.ID, StreamMarkerParams{UserID: owner.ID, Label: "After"}); !errors.Is(err, ErrChannelNotLive) {
+		t.Fatalf("expected ErrChannelNotLive after stopping, got %v", err)
+	}
+	markers, err = repo.ListStreamMarkers(session.ID)
+	requireAvailable(t, err, "list markers after stop")
+	if len(markers) != MaxStreamMarkersPerSession {
+		t.Fatalf("expected markers to outlive the stream, got %d", len(markers))
+	}
+}
+
 type failingDeleteObjectStorage struct {
 	fakeObjectStorage
 	err error
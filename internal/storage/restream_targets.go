@@ -0,0 +1,312 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"bitriver-live/internal/ingest"
+	"bitriver-live/internal/models"
+)
+
+const (
+	// MaxRestreamTargetsPerChannel caps how many restream targets a channel
+	// can configure.
+	MaxRestreamTargetsPerChannel = 5
+	// MaxRestreamLabelLength is the longest target label, in characters.
+	MaxRestreamLabelLength = 64
+	// MaxRestreamURLLength is the longest target ingest URL, in bytes.
+	MaxRestreamURLLength = 512
+	// MaxRestreamKeyLength is the longest target stream key, in bytes.
+	MaxRestreamKeyLength = 256
+)
+
+func normalizeRestreamLabel(label string) (string, error) {
+	label = strings.TrimSpace(label)
+	if label == "" {
+		return "", fmt.Errorf("label is required")
+	}
+	if utf8.RuneCountInString(label) > MaxRestreamLabelLength {
+		return "", fmt.Errorf("label must be %d characters or fewer", MaxRestreamLabelLength)
+	}
+	return label, nil
+}
+
+// normalizeRestreamURL accepts rtmp:// and rtmps:// ingest URLs without a
+// stream key, query, or credentials.
+func normalizeRestreamURL(raw string) (string, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return "", fmt.Errorf("url is required")
+	}
+	if len(raw) > MaxRestreamURLLength {
+		return "", fmt.Errorf("url must be %d bytes or fewer", MaxRestreamURLLength)
+	}
+	parsed, err := url.Parse(raw)
+	if err != nil || parsed.Host == "" {
+		return "", fmt.Errorf("url must be an absolute rtmp:// or rtmps:// URL")
+	}
+	scheme := strings.ToLower(parsed.Scheme)
+	if scheme != "rtmp" && scheme != "rtmps" {
+		return "", fmt.Errorf("url must use rtmp or rtmps")
+	}
+	if parsed.User != nil || parsed.RawQuery != "" || parsed.Fragment != "" {
+		return "", fmt.Errorf("url must not include credentials, a query, or a fragment; put the stream key in streamKey")
+	}
+	parsed.Scheme = scheme
+	return parsed.String(), nil
+}
+
+func normalizeRestreamKey(key string) (string, error) {
+	key = strings.TrimSpace(key)
+	if key == "" {
+		return "", fmt.Errorf("streamKey is required")
+	}
+	if len(key) > MaxRestreamKeyLength {
+		return "", fmt.Errorf("streamKey must be %d bytes or fewer", MaxRestreamKeyLength)
+	}
+	if strings.ContainsAny(key, "/?# \t\r\n") {
+		return "", fmt.Errorf("streamKey contains invalid characters")
+	}
+	return key, nil
+}
+
+// newRestreamTarget validates params and builds the target row for
+// channelID, sealing the stream key with secrets.
+func newRestreamTarget(secrets *secretSealer, channelID string, params RestreamTargetParams, now time.Time) (models.RestreamTarget, error) {
+	label, err := normalizeRestreamLabel(params.Label)
+	if err != nil {
+		return models.RestreamTarget{}, err
+	}
+	target, err := normalizeRestreamURL(params.URL)
+	if err != nil {
+		return models.RestreamTarget{}, err
+	}
+	key, err := normalizeRestreamKey(params.StreamKey)
+	if err != nil {
+		return models.RestreamTarget{}, err
+	}
+	id, err := generateID()
+	if err != nil {
+		return models.RestreamTarget{}, err
+	}
+	sealed, err := secrets.seal(id, key)
+	if err != nil {
+		return models.RestreamTarget{}, err
+	}
+	return models.RestreamTarget{
+		ID:                  id,
+		ChannelID:           channelID,
+		Label:               label,
+		URL:                 target,
+		StreamKeyCiphertext: sealed,
+		Enabled:             params.Enabled,
+		CreatedAt:           now,
+		UpdatedAt:           now,
+	}, nil
+}
+
+// applyRestreamTargetUpdate applies update to target, sealing a replacement
+// stream key with secrets.
+func applyRestreamTargetUpdate(secrets *secretSealer, target models.RestreamTarget, update RestreamTargetUpdate, now time.Time) (models.RestreamTarget, error) {
+	if update.Label != nil {
+		label, err := normalizeRestreamLabel(*update.Label)
+		if err != nil {
+			return models.RestreamTarget{}, err
+		}
+		target.Label = label
+	}
+	if update.URL != nil {
+		normalized, err := normalizeRestreamURL(*update.URL)
+		if err != nil {
+			return models.RestreamTarget{}, err
+		}
+		target.URL = normalized
+	}
+	if update.StreamKey != nil {
+		key, err := normalizeRestreamKey(*update.StreamKey)
+		if err != nil {
+			return models.RestreamTarget{}, err
+		}
+		sealed, err := secrets.seal(target.ID, key)
+		if err != nil {
+			return models.RestreamTarget{}, err
+		}
+		target.StreamKeyCiphertext = sealed
+	}
+	if update.Enabled != nil {
+		target.Enabled = *update.Enabled
+	}
+	target.UpdatedAt = now
+	return target, nil
+}
+
+// sortRestreamTargets orders targets by creation, oldest first.
+func sortRestreamTargets(targets []models.RestreamTarget) {
+	sort.Slice(targets, func(i, j int) bool {
+		if targets[i].CreatedAt.Equal(targets[j].CreatedAt) {
+			return targets[i].ID < targets[j].ID
+		}
+		return targets[i].CreatedAt.Before(targets[j].CreatedAt)
+	})
+}
+
+// bootRestreams opens the stream keys of the enabled targets for
+// BootStream. Targets whose key cannot be opened, for example after the
+// server secret key changed, are skipped with a warning so they cannot
+// block the stream.
+func bootRestreams(secrets *secretSealer, targets []models.RestreamTarget) []ingest.RestreamTarget {
+	var restreams []ingest.RestreamTarget
+	for _, target := range targets {
+		if !target.Enabled {
+			continue
+		}
+		key, err := secrets.open(target.ID, target.StreamKeyCiphertext)
+		if err != nil {
+			slog.Default().Warn("skipping restream target", "channel_id", target.ChannelID, "target_id", target.ID, "error", err)
+			continue
+		}
+		restreams = append(restreams, ingest.RestreamTarget{ID: target.ID, URL: target.URL, StreamKey: key})
+	}
+	return restreams
+}
+
+// fetchRestreamStatus asks controller for the restreams of channelID and
+// pairs each with its target. Controllers that cannot report restreams
+// have none.
+func fetchRestreamStatus(controller ingest.Controller, timeout time.Duration, channelID string, targets []models.RestreamTarget) ([]RestreamTargetStatus, error) {
+	if controller == nil {
+		return nil, ErrIngestControllerUnavailable
+	}
+	statuses := make([]RestreamTargetStatus, 0)
+	reporter, ok := controller.(ingest.RestreamReporter)
+	if !ok {
+		return statuses, nil
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), normalizeIngestTimeout(timeout))
+	defer cancel()
+	reported, err := reporter.RestreamStatus(ctx, channelID)
+	if err != nil {
+		return nil, fmt.Errorf("restream status: %w", err)
+	}
+	byID := make(map[string]models.RestreamTarget, len(targets))
+	for _, target := range targets {
+		byID[target.ID] = target
+	}
+	for _, entry := range reported {
+		statuses = append(statuses, RestreamTargetStatus{
+			TargetID:  entry.TargetID,
+			Target:    byID[entry.TargetID],
+			State:     entry.State,
+			Restarts:  entry.Restarts,
+			LastError: entry.LastError,
+		})
+	}
+	return statuses, nil
+}
+
+// restreamTargetsLocked returns the channel's targets in creation order.
+// The caller must hold s.mu.
+func (s *Storage) restreamTargetsLocked(channelID string) []models.RestreamTarget {
+	targets := make([]models.RestreamTarget, 0)
+	for _, target := range s.data.RestreamTargets {
+		if target.ChannelID == channelID {
+			targets = append(targets, target)
+		}
+	}
+	sortRestreamTargets(targets)
+	return targets
+}
+
+// CreateRestreamTarget adds a restream target to the channel. It returns
+// ErrRestreamTargetLimit once the channel has MaxRestreamTargetsPerChannel
+// targets and ErrSecretKeyUnavailable when no secret key is configured.
+func (s *Storage) CreateRestreamTarget(channelID string, params RestreamTargetParams) (models.RestreamTarget, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data.Channels[channelID]; !ok {
+		return models.RestreamTarget{}, notFound("channel", channelID)
+	}
+	if len(s.restreamTargetsLocked(channelID)) >= MaxRestreamTargetsPerChannel {
+		return models.RestreamTarget{}, ErrRestreamTargetLimit
+	}
+	target, err := newRestreamTarget(s.secrets, channelID, params, time.Now().UTC())
+	if err != nil {
+		return models.RestreamTarget{}, err
+	}
+	s.data.RestreamTargets[target.ID] = target
+	if err := s.persist(); err != nil {
+		delete(s.data.RestreamTargets, target.ID)
+		return models.RestreamTarget{}, err
+	}
+	return target, nil
+}
+
+// ListRestreamTargets returns the channel's restream targets in creation
+// order. An unknown channel has none.
+func (s *Storage) ListRestreamTargets(channelID string) ([]models.RestreamTarget, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.restreamTargetsLocked(channelID), nil
+}
+
+// UpdateRestreamTarget changes one of the channel's restream targets.
+// Changes apply from the channel's next stream.
+func (s *Storage) UpdateRestreamTarget(channelID, targetID string, update RestreamTargetUpdate) (models.RestreamTarget, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	target, ok := s.data.RestreamTargets[targetID]
+	if !ok || target.ChannelID != channelID {
+		return models.RestreamTarget{}, ErrRestreamTargetNotFound
+	}
+	updated, err := applyRestreamTargetUpdate(s.secrets, target, update, time.Now().UTC())
+	if err != nil {
+		return models.RestreamTarget{}, err
+	}
+	s.data.RestreamTargets[targetID] = updated
+	if err := s.persist(); err != nil {
+		s.data.RestreamTargets[targetID] = target
+		return models.RestreamTarget{}, err
+	}
+	return updated, nil
+}
+
+// DeleteRestreamTarget removes one of the channel's restream targets. A
+// restream already running for it continues until the stream ends.
+func (s *Storage) DeleteRestreamTarget(channelID, targetID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	target, ok := s.data.RestreamTargets[targetID]
+	if !ok || target.ChannelID != channelID {
+		return ErrRestreamTargetNotFound
+	}
+	delete(s.data.RestreamTargets, targetID)
+	if err := s.persist(); err != nil {
+		s.data.RestreamTargets[targetID] = target
+		return err
+	}
+	return nil
+}
+
+// RestreamStatus reports the state of the channel's restreams. It returns
+// ErrChannelNotLive when the channel is offline.
+func (s *Storage) RestreamStatus(channelID string) ([]RestreamTargetStatus, error) {
+	s.mu.RLock()
+	channel, ok := s.data.Channels[channelID]
+	if !ok {
+		s.mu.RUnlock()
+		return nil, notFound("channel", channelID)
+	}
+	if channel.CurrentSessionID == nil {
+		s.mu.RUnlock()
+		return nil, ErrChannelNotLive
+	}
+	targets := s.restreamTargetsLocked(channelID)
+	s.mu.RUnlock()
+	return fetchRestreamStatus(s.ingestController, s.ingestTimeout, channelID, targets)
+}
@@ -0,0 +1,306 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"bitriver-live/internal/models"
+)
+
+const (
+	// MaxScheduleEntriesPerChannel caps how many schedule entries a channel
+	// can announce.
+	MaxScheduleEntriesPerChannel = 50
+	// MaxScheduleTitleLength is the longest entry title, in characters.
+	MaxScheduleTitleLength = 140
+	// MaxScheduleCategoryLength is the longest entry category, in
+	// characters.
+	MaxScheduleCategoryLength = 64
+	// MaxScheduleDurationMinutes is the longest a scheduled stream can run.
+	MaxScheduleDurationMinutes = 24 * 60
+)
+
+// LoadScheduleLocation resolves an IANA time zone name for a schedule
+// entry. An empty name is UTC; the server's local zone is rejected because
+// it means something different on every host.
+func LoadScheduleLocation(name string) (*time.Location, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return time.UTC, nil
+	}
+	if name == "Local" {
+		return nil, fmt.Errorf("timeZone must be an IANA time zone name")
+	}
+	loc, err := time.LoadLocation(name)
+	if err != nil {
+		return nil, fmt.Errorf("timeZone %q is not a known IANA time zone", name)
+	}
+	return loc, nil
+}
+
+// normalizeScheduleEntry validates entry as a whole, so updates are checked
+// against the fields they leave unchanged.
+func normalizeScheduleEntry(entry models.ScheduleEntry) (models.ScheduleEntry, error) {
+	entry.Title = strings.TrimSpace(entry.Title)
+	if entry.Title == "" {
+		return models.ScheduleEntry{}, invalid("title", "title is required")
+	}
+	if utf8.RuneCountInString(entry.Title) > MaxScheduleTitleLength {
+		return models.ScheduleEntry{}, fmt.Errorf("title must be %d characters or fewer", MaxScheduleTitleLength)
+	}
+	entry.Category = strings.TrimSpace(entry.Category)
+	if utf8.RuneCountInString(entry.Category) > MaxScheduleCategoryLength {
+		return models.ScheduleEntry{}, fmt.Errorf("category must be %d characters or fewer", MaxScheduleCategoryLength)
+	}
+	if entry.StartsAt.IsZero() {
+		return models.ScheduleEntry{}, fmt.Errorf("startsAt is required")
+	}
+	entry.StartsAt = entry.StartsAt.UTC().Truncate(time.Second)
+	if entry.DurationMinutes < 1 || entry.DurationMinutes > MaxScheduleDurationMinutes {
+		return models.ScheduleEntry{}, fmt.Errorf("durationMinutes must be between 1 and %d", MaxScheduleDurationMinutes)
+	}
+	loc, err := LoadScheduleLocation(entry.TimeZone)
+	if err != nil {
+		return models.ScheduleEntry{}, err
+	}
+	entry.TimeZone = loc.String()
+	entry.Recurrence = strings.ToLower(strings.TrimSpace(entry.Recurrence))
+	switch entry.Recurrence {
+	case "":
+		if entry.RepeatUntil != nil {
+			return models.ScheduleEntry{}, fmt.Errorf("repeatUntil requires a recurrence")
+		}
+	case models.ScheduleRecurrenceWeekly:
+		if entry.RepeatUntil != nil {
+			until := entry.RepeatUntil.UTC().Truncate(time.Second)
+			if !until.After(entry.StartsAt) {
+				return models.ScheduleEntry{}, fmt.Errorf("repeatUntil must be after startsAt")
+			}
+			entry.RepeatUntil = &until
+		}
+	default:
+		return models.ScheduleEntry{}, fmt.Errorf("recurrence must be empty or %q", models.ScheduleRecurrenceWeekly)
+	}
+	return entry, nil
+}
+
+// newScheduleEntry validates params and builds the entry row for channelID.
+func newScheduleEntry(channelID string, params ScheduleEntryParams, now time.Time) (models.ScheduleEntry, error) {
+	entry, err := normalizeScheduleEntry(models.ScheduleEntry{
+		ChannelID:       channelID,
+		Title:           params.Title,
+		Category:        params.Category,
+		StartsAt:        params.StartsAt,
+		DurationMinutes: params.DurationMinutes,
+		TimeZone:        params.TimeZone,
+		Recurrence:      params.Recurrence,
+		RepeatUntil:     params.RepeatUntil,
+	})
+	if err != nil {
+		return models.ScheduleEntry{}, err
+	}
+	id, err := generateID()
+	if err != nil {
+		return models.ScheduleEntry{}, err
+	}
+	entry.ID = id
+	entry.CreatedAt = now
+	entry.UpdatedAt = now
+	return entry, nil
+}
+
+// applyScheduleEntryUpdate applies update to entry and bumps its sequence
+// so calendar clients pick up the change.
+func applyScheduleEntryUpdate(entry models.ScheduleEntry, update ScheduleEntryUpdate, now time.Time) (models.ScheduleEntry, error) {
+	if update.Title != nil {
+		entry.Title = *update.Title
+	}
+	if update.Category != nil {
+		entry.Category = *update.Category
+	}
+	if update.StartsAt != nil {
+		entry.StartsAt = *update.StartsAt
+	}
+	if update.DurationMinutes != nil {
+		entry.DurationMinutes = *update.DurationMinutes
+	}
+	if update.TimeZone != nil {
+		entry.TimeZone = *update.TimeZone
+	}
+	if update.Recurrence != nil {
+		entry.Recurrence = *update.Recurrence
+		if strings.TrimSpace(entry.Recurrence) == "" {
+			entry.RepeatUntil = nil
+		}
+	}
+	if update.RepeatUntil != nil {
+		if update.RepeatUntil.IsZero() {
+			entry.RepeatUntil = nil
+		} else {
+			until := *update.RepeatUntil
+			entry.RepeatUntil = &until
+		}
+	}
+	normalized, err := normalizeScheduleEntry(entry)
+	if err != nil {
+		return models.ScheduleEntry{}, err
+	}
+	normalized.Sequence++
+	normalized.UpdatedAt = now
+	return normalized, nil
+}
+
+// cloneScheduleEntry copies entry so callers cannot change RepeatUntil in
+// the store.
+func cloneScheduleEntry(entry models.ScheduleEntry) models.ScheduleEntry {
+	if entry.RepeatUntil != nil {
+		until := *entry.RepeatUntil
+		entry.RepeatUntil = &until
+	}
+	return entry
+}
+
+// sortScheduleEntries orders entries by their first occurrence.
+func sortScheduleEntries(entries []models.ScheduleEntry) {
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].StartsAt.Equal(entries[j].StartsAt) {
+			return entries[i].ID < entries[j].ID
+		}
+		return entries[i].StartsAt.Before(entries[j].StartsAt)
+	})
+}
+
+// scheduleEntriesLocked returns the channel's entries by first occurrence.
+// The caller must hold s.mu.
+func (s *Storage) scheduleEntriesLocked(channelID string) []models.ScheduleEntry {
+	entries := make([]models.ScheduleEntry, 0)
+	for _, entry := range s.data.ScheduleEntries {
+		if entry.ChannelID == channelID {
+			entries = append(entries, cloneScheduleEntry(entry))
+		}
+	}
+	sortScheduleEntries(entries)
+	return entries
+}
+
+// CreateScheduleEntry adds an entry to the channel's schedule. It returns
+// ErrScheduleEntryLimit once the channel has MaxScheduleEntriesPerChannel
+// entries.
+func (s *Storage) CreateScheduleEntry(channelID string, params ScheduleEntryParams) (models.ScheduleEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data.Channels[channelID]; !ok {
+		return models.ScheduleEntry{}, notFound("channel", channelID)
+	}
+	if len(s.scheduleEntriesLocked(channelID)) >= MaxScheduleEntriesPerChannel {
+		return models.ScheduleEntry{}, ErrScheduleEntryLimit
+	}
+	entry, err := newScheduleEntry(channelID, params, time.Now().UTC())
+	if err != nil {
+		return models.ScheduleEntry{}, err
+	}
+	s.data.ScheduleEntries[entry.ID] = entry
+	if err := s.persist(); err != nil {
+		delete(s.data.ScheduleEntries, entry.ID)
+		return models.ScheduleEntry{}, err
+	}
+	return cloneScheduleEntry(entry), nil
+}
+
+// ListScheduleEntries returns the channel's schedule by first occurrence.
+// An unknown channel has none.
+func (s *Storage) ListScheduleEntries(channelID string) ([]models.ScheduleEntry, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.scheduleEntriesLocked(channelID), nil
+}
+
+// UpdateScheduleEntry changes one of the channel's schedule entries.
+func (s *Storage) UpdateScheduleEntry(channelID, entryID string, update ScheduleEntryUpdate) (models.ScheduleEntry, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.data.ScheduleEntries[entryID]
+	if !ok || entry.ChannelID != channelID {
+		return models.ScheduleEntry{}, ErrScheduleEntryNotFound
+	}
+	updated, err := applyScheduleEntryUpdate(cloneScheduleEntry(entry), update, time.Now().UTC())
+	if err != nil {
+		return models.ScheduleEntry{}, err
+	}
+	s.data.ScheduleEntries[entryID] = updated
+	if err := s.persist(); err != nil {
+		s.data.ScheduleEntries[entryID] = entry
+		return models.ScheduleEntry{}, err
+	}
+	return cloneScheduleEntry(updated), nil
+}
+
+// DeleteScheduleEntry removes one of the channel's schedule entries.
+func (s *Storage) DeleteScheduleEntry(channelID, entryID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry, ok := s.data.ScheduleEntries[entryID]
+	if !ok || entry.ChannelID != channelID {
+		return ErrScheduleEntryNotFound
+	}
+	delete(s.data.ScheduleEntries, entryID)
+	if err := s.persist(); err != nil {
+		s.data.ScheduleEntries[entryID] = entry
+		return err
+	}
+	return nil
+}
+
+// IssueScheduleFeedToken gives the user a new followed-schedule feed token,
+// replacing any previous one so its URL stops working.
+func (s *Storage) IssueScheduleFeedToken(userID string) (models.ScheduleFeedToken, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data.Users[userID]; !ok {
+		return models.ScheduleFeedToken{}, notFound("user", userID)
+	}
+	nonce, err := generateID()
+	if err != nil {
+		return models.ScheduleFeedToken{}, err
+	}
+	token := models.ScheduleFeedToken{UserID: userID, Nonce: nonce, CreatedAt: time.Now().UTC()}
+	previous, hadPrevious := s.data.ScheduleFeedTokens[userID]
+	s.data.ScheduleFeedTokens[userID] = token
+	if err := s.persist(); err != nil {
+		if hadPrevious {
+			s.data.ScheduleFeedTokens[userID] = previous
+		} else {
+			delete(s.data.ScheduleFeedTokens, userID)
+		}
+		return models.ScheduleFeedToken{}, err
+	}
+	return token, nil
+}
+
+// GetScheduleFeedToken returns the user's current feed token, if any.
+func (s *Storage) GetScheduleFeedToken(userID string) (models.ScheduleFeedToken, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	token, ok := s.data.ScheduleFeedTokens[userID]
+	return token, ok
+}
+
+// RevokeScheduleFeedToken deletes the user's feed token. Revoking a user
+// without one succeeds.
+func (s *Storage) RevokeScheduleFeedToken(userID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	token, ok := s.data.ScheduleFeedTokens[userID]
+	if !ok {
+		return nil
+	}
+	delete(s.data.ScheduleFeedTokens, userID)
+	if err := s.persist(); err != nil {
+		s.data.ScheduleFeedTokens[userID] = token
+		return err
+	}
+	return nil
+}
@@ -0,0 +1,340 @@
+package storage
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"strings"
+)
+
+const (
+	// SecretKeyLength is the size, in bytes, of the server secret key that
+	// seals secrets at rest.
+	SecretKeyLength = 32
+
+	// sealedSecretPrefix marks values sealed with AES-256-GCM under the
+	// server secret key. The rest of the value is the base64 nonce followed
+	// by the ciphertext.
+	sealedSecretPrefix = "enc:v1:"
+	// keyedSecretPrefix marks values sealed with AES-256-GCM under a named
+	// encryption key: "enc:v2:<key id>:" followed by the base64 nonce and
+	// ciphertext.
+	keyedSecretPrefix = "enc:v2:"
+	// encryptedValuePrefix is shared by every sealed format. Values without
+	// it are plaintext written before encryption was enabled.
+	encryptedValuePrefix = "enc:"
+
+	maxEncryptionKeyIDLength = 32
+
+	// blindIndexContext derives the key that computes blind indexes from a
+	// sealing key, so the two uses never share key material.
+	blindIndexContext = "bitriver-live blind index v1"
+)
+
+var (
+	// ErrSecretKeyUnavailable indicates that a secret must be sealed or
+	// opened but no server secret key has been configured.
+	ErrSecretKeyUnavailable = errors.New("secret key not configured")
+	// ErrEncryptionKeyMissing indicates a value sealed under a named
+	// encryption key that is not in the configured keyring. Restore the key
+	// to read the value; it is never treated as plaintext.
+	ErrEncryptionKeyMissing = errors.New("encryption key not configured")
+	// errSealedSecretInvalid reports a sealed value that is malformed, was
+	// modified, or was sealed with a different key.
+	errSealedSecretInvalid = errors.New("sealed secret is invalid")
+)
+
+// ParseSecretKey decodes a server secret key given as base64 or hex. The
+// decoded key must be SecretKeyLength bytes.
+func ParseSecretKey(raw string) ([]byte, error) {
+	raw = strings.TrimSpace(raw)
+	if raw == "" {
+		return nil, ErrSecretKeyUnavailable
+	}
+	if key, err := hex.DecodeString(raw); err == nil && len(key) == SecretKeyLength {
+		return key, nil
+	}
+	for _, encoding := range []*base64.Encoding{base64.StdEncoding, base64.RawStdEncoding, base64.URLEncoding, base64.RawURLEncoding} {
+		if key, err := encoding.DecodeString(raw); err == nil && len(key) == SecretKeyLength {
+			return key, nil
+		}
+	}
+	return nil, fmt.Errorf("secret key must be %d bytes encoded as base64 or hex", SecretKeyLength)
+}
+
+// EncryptionKey is a named key in the field encryption keyring. The ID is
+// stored with every value sealed under the key so it can be retired later.
+type EncryptionKey struct {
+	ID  string
+	Key []byte
+}
+
+// ParseEncryptionKeys reads a keyring given as comma separated id:key
+// entries, each key encoded like the server secret key. The first entry seals
+// new values; the others only open values sealed before a rotation.
+func ParseEncryptionKeys(raw string) ([]EncryptionKey, error) {
+	var keys []EncryptionKey
+	seen := make(map[string]struct{})
+	for _, entry := range strings.Split(raw, ",") {
+		entry = strings.TrimSpace(entry)
+		if entry == "" {
+			continue
+		}
+		id, encoded, ok := strings.Cut(entry, ":")
+		id = strings.TrimSpace(id)
+		if !ok || !validEncryptionKeyID(id) {
+			return nil, fmt.Errorf("encryption key %q must be id:key with an id of up to %d letters, digits, - or _", entry, maxEncryptionKeyIDLength)
+		}
+		if _, dup := seen[id]; dup {
+			return nil, fmt.Errorf("encryption key id %q is listed twice", id)
+		}
+		seen[id] = struct{}{}
+		key, err := ParseSecretKey(encoded)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q: %w", id, err)
+		}
+		keys = append(keys, EncryptionKey{ID: id, Key: key})
+	}
+	if len(keys) == 0 {
+		return nil, ErrSecretKeyUnavailable
+	}
+	return keys, nil
+}
+
+func validEncryptionKeyID(id string) bool {
+	if id == "" || len(id) > maxEncryptionKeyIDLength {
+		return false
+	}
+	for _, r := range id {
+		if !(r >= 'a' && r <= 'z' || r >= 'A' && r <= 'Z' || r >= '0' && r <= '9' || r == '-' || r == '_') {
+			return false
+		}
+	}
+	return true
+}
+
+// secretSealer encrypts small secrets, such as restream keys and donation
+// addresses. Each value is bound to the ID of the row that owns it, so a
+// sealed value copied to another row does not open. A nil sealer reports
+// ErrSecretKeyUnavailable.
+//
+// The sealer holds the server secret key, which seals enc:v1 values, and an
+// optional keyring of named encryption keys. When a keyring is configured its
+// first key seals new values as enc:v2; every key keeps opening what it
+// sealed, so keys can be rotated without rewriting data up front.
+//
+// Each key also derives an HMAC key for blind indexes, which let a sealed
+// field be found by equality without storing it in plaintext.
+type secretSealer struct {
+	// legacy is the server secret key. It may be nil when only named keys
+	// are configured.
+	legacy      cipher.AEAD
+	legacyIndex []byte
+	// primaryID names the key that seals new values; empty seals with
+	// legacy.
+	primaryID string
+	keys      map[string]cipher.AEAD
+	indexKeys map[string][]byte
+	keyOrder  []string
+}
+
+func newSecretSealer(key []byte) (*secretSealer, error) {
+	return newSecretKeyring(key, nil)
+}
+
+// newSecretKeyring builds a sealer from the server secret key and the named
+// encryption keys, either of which may be empty. It returns nil when neither
+// is configured.
+func newSecretKeyring(secretKey []byte, keys []EncryptionKey) (*secretSealer, error) {
+	if len(secretKey) == 0 && len(keys) == 0 {
+		return nil, nil
+	}
+	sealer := &secretSealer{
+		keys:      make(map[string]cipher.AEAD, len(keys)),
+		indexKeys: make(map[string][]byte, len(keys)),
+	}
+	if len(secretKey) > 0 {
+		aead, err := newSecretAEAD(secretKey)
+		if err != nil {
+			return nil, fmt.Errorf("secret key: %w", err)
+		}
+		sealer.legacy = aead
+		sealer.legacyIndex = deriveBlindIndexKey(secretKey)
+	}
+	for _, key := range keys {
+		if !validEncryptionKeyID(key.ID) {
+			return nil, fmt.Errorf("encryption key id %q is invalid", key.ID)
+		}
+		if _, dup := sealer.keys[key.ID]; dup {
+			return nil, fmt.Errorf("encryption key id %q is listed twice", key.ID)
+		}
+		aead, err := newSecretAEAD(key.Key)
+		if err != nil {
+			return nil, fmt.Errorf("encryption key %q: %w", key.ID, err)
+		}
+		sealer.keys[key.ID] = aead
+		sealer.indexKeys[key.ID] = deriveBlindIndexKey(key.Key)
+		sealer.keyOrder = append(sealer.keyOrder, key.ID)
+		if sealer.primaryID == "" {
+			sealer.primaryID = key.ID
+		}
+	}
+	return sealer, nil
+}
+
+func deriveBlindIndexKey(key []byte) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(blindIndexContext))
+	return mac.Sum(nil)
+}
+
+func newSecretAEAD(key []byte) (cipher.AEAD, error) {
+	if len(key) != SecretKeyLength {
+		return nil, fmt.Errorf("key must be %d bytes, got %d", SecretKeyLength, len(key))
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
+
+func (s *secretSealer) seal(ownerID, plaintext string) (string, error) {
+	if s == nil {
+		return "", ErrSecretKeyUnavailable
+	}
+	aead, prefix := s.legacy, sealedSecretPrefix
+	if s.primaryID != "" {
+		aead, prefix = s.keys[s.primaryID], keyedSecretPrefix+s.primaryID+":"
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", fmt.Errorf("generate nonce: %w", err)
+	}
+	sealed := aead.Seal(nonce, nonce, []byte(plaintext), []byte(ownerID))
+	return prefix + base64.RawStdEncoding.EncodeToString(sealed), nil
+}
+
+func (s *secretSealer) open(ownerID, sealed string) (string, error) {
+	if s == nil {
+		return "", ErrSecretKeyUnavailable
+	}
+	if encoded, ok := strings.CutPrefix(sealed, keyedSecretPrefix); ok {
+		id, encoded, ok := strings.Cut(encoded, ":")
+		if !ok {
+			return "", errSealedSecretInvalid
+		}
+		aead, ok := s.keys[id]
+		if !ok {
+			return "", fmt.Errorf("%w: %q", ErrEncryptionKeyMissing, id)
+		}
+		return openSealed(aead, ownerID, encoded)
+	}
+	encoded, ok := strings.CutPrefix(sealed, sealedSecretPrefix)
+	if !ok {
+		return "", errSealedSecretInvalid
+	}
+	if s.legacy == nil {
+		return "", fmt.Errorf("open %s value: %w", strings.TrimSuffix(sealedSecretPrefix, ":"), ErrSecretKeyUnavailable)
+	}
+	return openSealed(s.legacy, ownerID, encoded)
+}
+
+func openSealed(aead cipher.AEAD, ownerID, encoded string) (string, error) {
+	data, err := base64.RawStdEncoding.DecodeString(encoded)
+	if err != nil || len(data) < aead.NonceSize() {
+		return "", errSealedSecretInvalid
+	}
+	nonce, ciphertext := data[:aead.NonceSize()], data[aead.NonceSize():]
+	plaintext, err := aead.Open(nil, nonce, ciphertext, []byte(ownerID))
+	if err != nil {
+		return "", errSealedSecretInvalid
+	}
+	return string(plaintext), nil
+}
+
+// EncryptString seals a sensitive field value bound to ownerID. Empty values
+// stay empty, and without any key configured the value is stored as
+// plaintext, as it was before field encryption.
+func (s *secretSealer) EncryptString(ownerID, plaintext string) (string, error) {
+	if s == nil || plaintext == "" {
+		return plaintext, nil
+	}
+	return s.seal(ownerID, plaintext)
+}
+
+// DecryptString opens a value written by EncryptString. Values without the
+// enc: prefix are plaintext from before encryption was enabled and are
+// returned unchanged. A value sealed under a key that is not configured fails
+// with ErrEncryptionKeyMissing, and a modified value with an invalid-secret
+// error, rather than being returned as-is.
+func (s *secretSealer) DecryptString(ownerID, value string) (string, error) {
+	if !strings.HasPrefix(value, encryptedValuePrefix) {
+		return value, nil
+	}
+	return s.open(ownerID, value)
+}
+
+// needsRotation reports whether value is plaintext or sealed under a key
+// other than the one new values are sealed with.
+func (s *secretSealer) needsRotation(value string) bool {
+	if s == nil || value == "" {
+		return false
+	}
+	if s.primaryID == "" {
+		return !strings.HasPrefix(value, sealedSecretPrefix)
+	}
+	return !strings.HasPrefix(value, keyedSecretPrefix+s.primaryID+":")
+}
+
+// reseal opens value and seals it again under the primary key. Plaintext
+// values are sealed for the first time.
+func (s *secretSealer) reseal(ownerID, value string) (string, error) {
+	plaintext, err := s.DecryptString(ownerID, value)
+	if err != nil {
+		return "", err
+	}
+	return s.seal(ownerID, plaintext)
+}
+
+// blindIndex returns a keyed digest of value under the primary key, so a
+// sealed field can be looked up by equality. Without a key it is empty.
+func (s *secretSealer) blindIndex(value string) string {
+	if s == nil {
+		return ""
+	}
+	if s.primaryID != "" {
+		return computeBlindIndex(s.indexKeys[s.primaryID], value)
+	}
+	return computeBlindIndex(s.legacyIndex, value)
+}
+
+// blindIndexes returns the index of value under every configured key, the
+// primary key first, so rows indexed before a rotation are still found.
+func (s *secretSealer) blindIndexes(value string) []string {
+	if s == nil {
+		return nil
+	}
+	indexes := []string{s.blindIndex(value)}
+	for _, id := range s.keyOrder {
+		if id != s.primaryID {
+			indexes = append(indexes, computeBlindIndex(s.indexKeys[id], value))
+		}
+	}
+	if s.primaryID != "" && s.legacyIndex != nil {
+		indexes = append(indexes, computeBlindIndex(s.legacyIndex, value))
+	}
+	return indexes
+}
+
+func computeBlindIndex(key []byte, value string) string {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(value))
+	return hex.EncodeToString(mac.Sum(nil))
+}
@@ -0,0 +1,93 @@
+package storage
+
+import (
+	"encoding/base64"
+	"encoding/hex"
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestParseSecretKey(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	for _, raw := range []string{hex.EncodeToString(key), base64.StdEncoding.EncodeToString(key), base64.RawURLEncoding.EncodeToString(key)} {
+		parsed, err := ParseSecretKey(" " + raw + "\n")
+		if err != nil || string(parsed) != string(key) {
+			t.Fatalf("ParseSecretKey(%q) = %q, %v", raw, parsed, err)
+		}
+	}
+	if _, err := ParseSecretKey(""); !errors.Is(err, ErrSecretKeyUnavailable) {
+		t.Fatalf("expected an empty key to be unavailable, got %v", err)
+	}
+	if _, err := ParseSecretKey(hex.EncodeToString(key[:16])); err == nil {
+		t.Fatal("expected a short key to be rejected")
+	}
+}
+
+func TestSecretSealerBindsValuesToOwner(t *testing.T) {
+	sealer, err := newSecretSealer([]byte("0123456789abcdef0123456789abcdef"))
+	if err != nil {
+		t.Fatalf("newSecretSealer: %v", err)
+	}
+	sealed, err := sealer.seal("target-1", "live_secret")
+	if err != nil {
+		t.Fatalf("seal: %v", err)
+	}
+	if !strings.HasPrefix(sealed, sealedSecretPrefix) || strings.Contains(sealed, "live_secret") {
+		t.Fatalf("expected a prefixed ciphertext, got %q", sealed)
+	}
+	if again, _ := sealer.seal("target-1", "live_secret"); again == sealed {
+		t.Fatal("expected a fresh nonce per seal")
+	}
+	if opened, err := sealer.open("target-1", sealed); err != nil || opened != "live_secret" {
+		t.Fatalf("open = %q, %v", opened, err)
+	}
+	if _, err := sealer.open("target-2", sealed); !errors.Is(err, errSealedSecretInvalid) {
+		t.Fatalf("expected a value moved to another owner to fail, got %v", err)
+	}
+	if _, err := sealer.open("target-1", "live_secret"); !errors.Is(err, errSealedSecretInvalid) {
+		t.Fatalf("expected plaintext to be rejected, got %v", err)
+	}
+
+	other, err := newSecretSealer([]byte("fedcba9876543210fedcba9876543210"))
+	if err != nil {
+		t.Fatalf("newSecretSealer: %v", err)
+	}
+	if _, err := other.open("target-1", sealed); !errors.Is(err, errSealedSecretInvalid) {
+		t.Fatalf("expected another key to fail, got %v", err)
+	}
+	var missing *secretSealer
+	if _, err := missing.seal("target-1", "live_secret"); !errors.Is(err, ErrSecretKeyUnavailable) {
+		t.Fatalf("expected a nil sealer to be unavailable, got %v", err)
+	}
+	if _, err := newSecretSealer([]byte("short")); err == nil {
+		t.Fatal("expected a short key to be rejected")
+	}
+}
+
+func TestSecretSealerBlindIndexes(t *testing.T) {
+	old, err := newSecretKeyring(nil, []EncryptionKey{testEncryptionKeyOld})
+	if err != nil {
+		t.Fatalf("newSecretKeyring: %v", err)
+	}
+	index := old.blindIndex("github|1234")
+	if index == "" || index != old.blindIndex("github|1234") || index == old.blindIndex("github|1235") {
+		t.Fatalf("expected a stable index per value, got %q", index)
+	}
+	if strings.Contains(index, "1234") {
+		t.Fatalf("expected the index not to reveal the value, got %q", index)
+	}
+
+	rotated, err := newSecretKeyring(nil, []EncryptionKey{testEncryptionKeyNew, testEncryptionKeyOld})
+	if err != nil {
+		t.Fatalf("newSecretKeyring: %v", err)
+	}
+	indexes := rotated.blindIndexes("github|1234")
+	if len(indexes) != 2 || indexes[0] == index || indexes[1] != index {
+		t.Fatalf("expected the primary index first and the retired one after it, got %v", indexes)
+	}
+	var missing *secretSealer
+	if missing.blindIndex("github|1234") != "" || missing.blindIndexes("github|1234") != nil {
+		t.Fatal("expected no index without a key")
+	}
+}
@@ -0,0 +1,31 @@
+package storage
+
+// UpdateSessionPeak raises the session's peak concurrent viewers to
+// concurrent if that is higher, returning the stored peak. Concurrent callers
+// are serialized by s.mu, so the peak only ever grows.
+func (s *Storage) UpdateSessionPeak(sessionID string, concurrent int) (int, error) {
+	if concurrent < 0 {
+		return 0, invalid("concurrent", "concurrent viewers cannot be negative")
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	session, ok := s.data.StreamSessions[sessionID]
+	if !ok {
+		return 0, notFound("stream session", sessionID)
+	}
+	if session.EndedAt != nil {
+		return session.PeakConcurrent, ErrStreamSessionEnded
+	}
+	if concurrent <= session.PeakConcurrent {
+		return session.PeakConcurrent, nil
+	}
+	original := session
+	session.PeakConcurrent = concurrent
+	s.data.StreamSessions[sessionID] = session
+	if err := s.persist(); err != nil {
+		s.data.StreamSessions[sessionID] = original
+		return 0, err
+	}
+	return concurrent, nil
+}
@@ -1,11 +1,14 @@
 package storage
 
 import (
+	"bufio"
+	"compress/gzip"
 	"context"
 	"encoding/json"
 	"fmt"
 	"io"
 	"os"
+	"path/filepath"
 	"time"
 
 	"bitriver-live/internal/models"
@@ -15,54 +18,98 @@ import (
 // datastore, grouping each model collection by its primary identifier so it can
 // be persisted and later replayed into another backing store.
 type Snapshot struct {
-	Users               map[string]models.User          `json:"users"`
-	OAuthAccounts       map[string]models.OAuthAccount  `json:"oauthAccounts"`
-	Channels            map[string]models.Channel       `json:"channels"`
-	StreamSessions      map[string]models.StreamSession `json:"streamSessions"`
-	ChatMessages        map[string]models.ChatMessage   `json:"chatMessages"`
-	ChatBans            map[string]map[string]time.Time `json:"chatBans"`
-	ChatTimeouts        map[string]map[string]time.Time `json:"chatTimeouts"`
-	ChatBanActors       map[string]map[string]string    `json:"chatBanActors"`
-	ChatBanReasons      map[string]map[string]string    `json:"chatBanReasons"`
-	ChatTimeoutActors   map[string]map[string]string    `json:"chatTimeoutActors"`
-	ChatTimeoutReasons  map[string]map[string]string    `json:"chatTimeoutReasons"`
-	ChatTimeoutIssuedAt map[string]map[string]time.Time `json:"chatTimeoutIssuedAt"`
-	ChatReports         map[string]models.ChatReport    `json:"chatReports"`
-	Tips                map[string]models.Tip           `json:"tips"`
-	Subscriptions       map[string]models.Subscription  `json:"subscriptions"`
-	Profiles            map[string]models.Profile       `json:"profiles"`
-	Follows             map[string]map[string]time.Time `json:"follows"`
-	Recordings          map[string]models.Recording     `json:"recordings"`
-	Uploads             map[string]models.Upload        `json:"uploads"`
-	ClipExports         map[string]models.ClipExport    `json:"clipExports"`
+	Users               map[string]models.User                     `json:"users"`
+	OAuthAccounts       map[string]models.OAuthAccount             `json:"oauthAccounts"`
+	APITokens           map[string]models.APIToken                 `json:"apiTokens"`
+	Channels            map[string]models.Channel                  `json:"channels"`
+	StreamSessions      map[string]models.StreamSession            `json:"streamSessions"`
+	ChatMessages        map[string]models.ChatMessage              `json:"chatMessages"`
+	ChatBans            map[string]map[string]time.Time            `json:"chatBans"`
+	ChatTimeouts        map[string]map[string]time.Time            `json:"chatTimeouts"`
+	ChatBanActors       map[string]map[string]string               `json:"chatBanActors"`
+	ChatBanReasons      map[string]map[string]string               `json:"chatBanReasons"`
+	ChatTimeoutActors   map[string]map[string]string               `json:"chatTimeoutActors"`
+	ChatTimeoutReasons  map[string]map[string]string               `json:"chatTimeoutReasons"`
+	ChatTimeoutIssuedAt map[string]map[string]time.Time            `json:"chatTimeoutIssuedAt"`
+	ChatReports         map[string]models.ChatReport               `json:"chatReports"`
+	ModerationActions   map[string]models.ModerationAction         `json:"moderationActions"`
+	Tips                map[string]models.Tip                      `json:"tips"`
+	Subscriptions       map[string]models.Subscription             `json:"subscriptions"`
+	Profiles            map[string]models.Profile                  `json:"profiles"`
+	Follows             map[string]map[string]time.Time            `json:"follows"`
+	ChannelEditors      map[string]map[string]models.ChannelEditor `json:"channelEditors"`
+	Recordings          map[string]models.Recording                `json:"recordings"`
+	Uploads             map[string]models.Upload                   `json:"uploads"`
+	ClipExports         map[string]models.ClipExport               `json:"clipExports"`
+
+	NotificationPreferences map[string]models.NotificationPreferences     `json:"notificationPreferences"`
+	StreamMarkers           map[string]models.StreamMarker                `json:"streamMarkers"`
+	RestreamTargets         map[string]models.RestreamTarget              `json:"restreamTargets"`
+	ChatAppeals             map[string]models.ChatAppeal                  `json:"chatAppeals"`
+	PlaybackPreferences     map[string]models.PlaybackPreferences         `json:"playbackPreferences"`
+	BadgeDefinitions        map[string]models.BadgeDefinition             `json:"badgeDefinitions"`
+	BadgeGrants             map[string]map[string]models.BadgeGrant       `json:"badgeGrants"`
+	ScheduleEntries         map[string]models.ScheduleEntry               `json:"scheduleEntries"`
+	ScheduleFeedTokens      map[string]models.ScheduleFeedToken           `json:"scheduleFeedTokens"`
+	ChannelStorage          map[string]models.ChannelStorage              `json:"channelStorage"`
+	ChatPins                map[string]models.ChatPin                     `json:"chatPins"`
+	ChatSequences           map[string]int64                              `json:"chatSequences"`
+	ChannelModerators       map[string]map[string]models.ChannelModerator `json:"channelModerators"`
+	SecurityEvents          map[string]models.SecurityEvent               `json:"securityEvents"`
+	Notifications           map[string]models.Notification                `json:"notifications"`
+	CoStreamInvites         map[string]models.CoStreamInvite              `json:"coStreamInvites"`
+	FeatureFlags            map[string]models.FeatureFlag                 `json:"featureFlags"`
+	ChannelSlugRedirects    map[string]models.ChannelSlugRedirect         `json:"channelSlugRedirects"`
 }
 
 // SnapshotCounts summarises the size of each collection stored in a Snapshot to
 // help operators understand how much data will be serialised and imported.
 type SnapshotCounts struct {
-	Users                  int
-	OAuthAccounts          int
-	Channels               int
-	StreamSessions         int
-	StreamSessionManifests int
-	ChatMessages           int
-	ChatBans               int
-	ChatTimeouts           int
-	ChatReports            int
-	Tips                   int
-	Subscriptions          int
-	Profiles               int
-	Follows                int
-	Recordings             int
-	RecordingRenditions    int
-	RecordingThumbnails    int
-	Uploads                int
-	ClipExports            int
+	Users                   int
+	OAuthAccounts           int
+	APITokens               int
+	Channels                int
+	StreamSessions          int
+	StreamSessionManifests  int
+	ChatMessages            int
+	ChatBans                int
+	ChatTimeouts            int
+	ChatReports             int
+	ModerationActions       int
+	Tips                    int
+	Subscriptions           int
+	Profiles                int
+	Follows                 int
+	ChannelEditors          int
+	Recordings              int
+	RecordingRenditions     int
+	RecordingThumbnails     int
+	Uploads                 int
+	ClipExports             int
+	NotificationPreferences int
+	StreamMarkers           int
+	RestreamTargets         int
+	ChatAppeals             int
+	PlaybackPreferences     int
+	BadgeDefinitions        int
+	BadgeGrants             int
+	ScheduleEntries         int
+	ScheduleFeedTokens      int
+	ChannelStorage          int
+	ChatPins                int
+	ChatSequences           int
+	ChannelModerators       int
+	SecurityEvents          int
+	Notifications           int
+	CoStreamInvites         int
+	FeatureFlags            int
+	ChannelSlugRedirects    int
 }
 
 // LoadSnapshotFromJSON reads a previously exported Snapshot from disk,
 // rehydrating the datastore state serialised in JSON so it can be imported or
-// inspected.
+// inspected. Gzip-compressed snapshots are detected and decompressed
+// transparently.
 func LoadSnapshotFromJSON(path string) (*Snapshot, error) {
 	file, err := os.Open(path)
 	if err != nil {
@@ -72,7 +119,20 @@ func LoadSnapshotFromJSON(path string) (*Snapshot, error) {
 		_ = file.Close()
 	}()
 
-	decoder := json.NewDecoder(file)
+	buffered := bufio.NewReader(file)
+	var reader io.Reader = buffered
+	if magic, _ := buffered.Peek(2); len(magic) == 2 && magic[0] == 0x1f && magic[1] == 0x8b {
+		gz, err := gzip.NewReader(buffered)
+		if err != nil {
+			return nil, fmt.Errorf("open gzip snapshot %s: %w", path, err)
+		}
+		defer func() {
+			_ = gz.Close()
+		}()
+		reader = gz
+	}
+
+	decoder := json.NewDecoder(reader)
 	var snapshot Snapshot
 	if err := decoder.Decode(&snapshot); err != nil {
 		if err == io.EOF {
@@ -85,6 +145,76 @@ func LoadSnapshotFromJSON(path string) (*Snapshot, error) {
 	return &snapshot, nil
 }
 
+// WriteSnapshotJSON serialises the snapshot to w, optionally gzip-compressing
+// the output. The JSON layout matches the JSON datastore so an export can be
+// used directly as a store file.
+func WriteSnapshotJSON(w io.Writer, snapshot *Snapshot, compress bool) error {
+	if snapshot == nil {
+		return fmt.Errorf("snapshot is required")
+	}
+	snapshot.ensureInitialized()
+
+	if !compress {
+		encoder := json.NewEncoder(w)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(snapshot); err != nil {
+			return fmt.Errorf("encode snapshot: %w", err)
+		}
+		return nil
+	}
+
+	gz := gzip.NewWriter(w)
+	if err := json.NewEncoder(gz).Encode(snapshot); err != nil {
+		_ = gz.Close()
+		return fmt.Errorf("encode snapshot: %w", err)
+	}
+	if err := gz.Close(); err != nil {
+		return fmt.Errorf("flush gzip snapshot: %w", err)
+	}
+	return nil
+}
+
+// SaveSnapshotToJSON writes the snapshot to path via a temporary file and
+// rename so an interrupted export never leaves a truncated backup behind.
+func SaveSnapshotToJSON(path string, snapshot *Snapshot, compress bool) error {
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return fmt.Errorf("create snapshot dir: %w", err)
+	}
+
+	tmpFile, err := os.CreateTemp(dir, "snapshot-*.tmp")
+	if err != nil {
+		return fmt.Errorf("create temp snapshot file: %w", err)
+	}
+	tmpPath := tmpFile.Name()
+	success := false
+	defer func() {
+		if !success {
+			_ = tmpFile.Close()
+			_ = os.Remove(tmpPath)
+		}
+	}()
+
+	buffered := bufio.NewWriter(tmpFile)
+	if err := WriteSnapshotJSON(buffered, snapshot, compress); err != nil {
+		return err
+	}
+	if err := buffered.Flush(); err != nil {
+		return fmt.Errorf("write snapshot %s: %w", path, err)
+	}
+	if err := tmpFile.Sync(); err != nil {
+		return fmt.Errorf("flush snapshot %s: %w", path, err)
+	}
+	if err := tmpFile.Close(); err != nil {
+		return fmt.Errorf("close temp snapshot file: %w", err)
+	}
+	if err := os.Rename(tmpPath, path); err != nil {
+		return fmt.Errorf("replace snapshot %s: %w", path, err)
+	}
+	success = true
+	return nil
+}
+
 func (s *Snapshot) ensureInitialized() {
 	if s.Users == nil {
 		s.Users = make(map[string]models.User)
@@ -92,6 +222,9 @@ func (s *Snapshot) ensureInitialized() {
 	if s.OAuthAccounts == nil {
 		s.OAuthAccounts = make(map[string]models.OAuthAccount)
 	}
+	if s.APITokens == nil {
+		s.APITokens = make(map[string]models.APIToken)
+	}
 	if s.Channels == nil {
 		s.Channels = make(map[string]models.Channel)
 	}
@@ -137,6 +270,9 @@ func (s *Snapshot) ensureInitialized() {
 	if s.Follows == nil {
 		s.Follows = make(map[string]map[string]time.Time)
 	}
+	if s.ChannelEditors == nil {
+		s.ChannelEditors = make(map[string]map[string]models.ChannelEditor)
+	}
 	if s.Recordings == nil {
 		s.Recordings = make(map[string]models.Recording)
 	}
@@ -146,6 +282,63 @@ func (s *Snapshot) ensureInitialized() {
 	if s.ClipExports == nil {
 		s.ClipExports = make(map[string]models.ClipExport)
 	}
+	if s.NotificationPreferences == nil {
+		s.NotificationPreferences = make(map[string]models.NotificationPreferences)
+	}
+	if s.StreamMarkers == nil {
+		s.StreamMarkers = make(map[string]models.StreamMarker)
+	}
+	if s.RestreamTargets == nil {
+		s.RestreamTargets = make(map[string]models.RestreamTarget)
+	}
+	if s.ChatAppeals == nil {
+		s.ChatAppeals = make(map[string]models.ChatAppeal)
+	}
+	if s.ModerationActions == nil {
+		s.ModerationActions = make(map[string]models.ModerationAction)
+	}
+	if s.PlaybackPreferences == nil {
+		s.PlaybackPreferences = make(map[string]models.PlaybackPreferences)
+	}
+	if s.BadgeDefinitions == nil {
+		s.BadgeDefinitions = make(map[string]models.BadgeDefinition)
+	}
+	if s.BadgeGrants == nil {
+		s.BadgeGrants = make(map[string]map[string]models.BadgeGrant)
+	}
+	if s.ScheduleEntries == nil {
+		s.ScheduleEntries = make(map[string]models.ScheduleEntry)
+	}
+	if s.ScheduleFeedTokens == nil {
+		s.ScheduleFeedTokens = make(map[string]models.ScheduleFeedToken)
+	}
+	if s.ChannelStorage == nil {
+		s.ChannelStorage = make(map[string]models.ChannelStorage)
+	}
+	if s.ChatPins == nil {
+		s.ChatPins = make(map[string]models.ChatPin)
+	}
+	if s.ChatSequences == nil {
+		s.ChatSequences = make(map[string]int64)
+	}
+	if s.ChannelModerators == nil {
+		s.ChannelModerators = make(map[string]map[string]models.ChannelModerator)
+	}
+	if s.SecurityEvents == nil {
+		s.SecurityEvents = make(map[string]models.SecurityEvent)
+	}
+	if s.Notifications == nil {
+		s.Notifications = make(map[string]models.Notification)
+	}
+	if s.CoStreamInvites == nil {
+		s.CoStreamInvites = make(map[string]models.CoStreamInvite)
+	}
+	if s.FeatureFlags == nil {
+		s.FeatureFlags = make(map[string]models.FeatureFlag)
+	}
+	if s.ChannelSlugRedirects == nil {
+		s.ChannelSlugRedirects = make(map[string]models.ChannelSlugRedirect)
+	}
 }
 
 // Counts walks a Snapshot and returns the SnapshotCounts summary reflecting
@@ -157,6 +350,7 @@ func (s *Snapshot) Counts() SnapshotCounts {
 	counts := SnapshotCounts{
 		Users:          len(s.Users),
 		OAuthAccounts:  len(s.OAuthAccounts),
+		APITokens:      len(s.APITokens),
 		Channels:       len(s.Channels),
 		StreamSessions: len(s.StreamSessions),
 		ChatMessages:   len(s.ChatMessages),
@@ -167,10 +361,37 @@ func (s *Snapshot) Counts() SnapshotCounts {
 		Recordings:     len(s.Recordings),
 		Uploads:        len(s.Uploads),
 		ClipExports:    len(s.ClipExports),
+
+		NotificationPreferences: len(s.NotificationPreferences),
+		StreamMarkers:           len(s.StreamMarkers),
+		RestreamTargets:         len(s.RestreamTargets),
+		ChatAppeals:             len(s.ChatAppeals),
+		ModerationActions:       len(s.ModerationActions),
+		PlaybackPreferences:     len(s.PlaybackPreferences),
+		BadgeDefinitions:        len(s.BadgeDefinitions),
+		ScheduleEntries:         len(s.ScheduleEntries),
+		ScheduleFeedTokens:      len(s.ScheduleFeedTokens),
+		ChannelStorage:          len(s.ChannelStorage),
+		ChatPins:                len(s.ChatPins),
+		ChatSequences:           len(s.ChatSequences),
+		SecurityEvents:          len(s.SecurityEvents),
+		Notifications:           len(s.Notifications),
+		CoStreamInvites:         len(s.CoStreamInvites),
+		FeatureFlags:            len(s.FeatureFlags),
+		ChannelSlugRedirects:    len(s.ChannelSlugRedirects),
+	}
+	for _, grants := range s.BadgeGrants {
+		counts.BadgeGrants += len(grants)
 	}
 	for _, follows := range s.Follows {
 		counts.Follows += len(follows)
 	}
+	for _, editors := range s.ChannelEditors {
+		counts.ChannelEditors += len(editors)
+	}
+	for _, moderators := range s.ChannelModerators {
+		counts.ChannelModerators += len(moderators)
+	}
 	for _, bans := range s.ChatBans {
 		counts.ChatBans += len(bans)
 	}
@@ -187,16 +408,139 @@ func (s *Snapshot) Counts() SnapshotCounts {
 	return counts
 }
 
-// ImportSnapshotToPostgres hands a Snapshot to the postgresRepository so the
-// serialised datastore state can be bulk-loaded into Postgres.
-func ImportSnapshotToPostgres(ctx context.Context, repo Repository, snapshot *Snapshot) error {
+type snapshotImportConfig struct {
+	continueOnError bool
+}
+
+// SnapshotImportOption customises ImportSnapshotToPostgres.
+type SnapshotImportOption func(*snapshotImportConfig)
+
+// WithSnapshotContinueOnError keeps importing when Postgres rejects a row.
+// Rejected rows are listed in the report's Failures, and the steps they
+// belong to are retried on the next run.
+func WithSnapshotContinueOnError() SnapshotImportOption {
+	return func(cfg *snapshotImportConfig) {
+		cfg.continueOnError = true
+	}
+}
+
+// Snapshot import step statuses.
+const (
+	// SnapshotStepImported means every row of the step is in the database.
+	SnapshotStepImported = "imported"
+	// SnapshotStepResumed means an earlier run imported the step's input and
+	// this run skipped it.
+	SnapshotStepResumed = "resumed"
+	// SnapshotStepIncomplete means some of the step's rows were rejected.
+	SnapshotStepIncomplete = "incomplete"
+)
+
+// SnapshotImportReport describes a snapshot import or dry run. Violations
+// holds what ValidateSnapshot found; Failures holds the rows Postgres
+// rejected during an import with WithSnapshotContinueOnError.
+type SnapshotImportReport struct {
+	DryRun     bool                  `json:"dryRun,omitempty"`
+	Violations []SnapshotRowIssue    `json:"violations"`
+	Steps      []SnapshotStepResult  `json:"steps"`
+	Tables     []SnapshotTableResult `json:"tables"`
+	Failures   []SnapshotRowIssue    `json:"failures"`
+}
+
+// SnapshotStepResult is the outcome of one import step.
+type SnapshotStepResult struct {
+	Step   string `json:"step"`
+	Status string `json:"status"`
+}
+
+// SnapshotTableResult counts the rows an import wrote to one table.
+// Existing rows were already in the table and were left untouched.
+type SnapshotTableResult struct {
+	Table     string `json:"table"`
+	Attempted int    `json:"attempted"`
+	Inserted  int    `json:"inserted"`
+	Existing  int    `json:"existing"`
+	Failed    int    `json:"failed"`
+}
+
+func (t *SnapshotTableResult) count(inserted bool) {
+	if inserted {
+		t.Inserted++
+	} else {
+		t.Existing++
+	}
+}
+
+func newSnapshotImportReport() *SnapshotImportReport {
+	return &SnapshotImportReport{
+		Violations: make([]SnapshotRowIssue, 0),
+		Steps:      make([]SnapshotStepResult, 0),
+		Tables:     make([]SnapshotTableResult, 0),
+		Failures:   make([]SnapshotRowIssue, 0),
+	}
+}
+
+func (r *SnapshotImportReport) addStep(name, status string, im *snapshotImporter) {
+	r.Steps = append(r.Steps, SnapshotStepResult{Step: name, Status: status})
+	if im == nil {
+		return
+	}
+	r.Tables = append(r.Tables, im.tables...)
+	r.Failures = append(r.Failures, im.failures...)
+}
+
+// Inserted returns how many rows the import added to table.
+func (r *SnapshotImportReport) Inserted(table string) int {
+	if r == nil {
+		return 0
+	}
+	total := 0
+	for _, result := range r.Tables {
+		if result.Table == table {
+			total += result.Inserted
+		}
+	}
+	return total
+}
+
+// SaveSnapshotImportReport writes the report to path as indented JSON.
+func SaveSnapshotImportReport(path string, report *SnapshotImportReport) error {
+	normalized := newSnapshotImportReport()
+	if report != nil {
+		normalized.DryRun = report.DryRun
+		normalized.Violations = append(normalized.Violations, report.Violations...)
+		normalized.Steps = append(normalized.Steps, report.Steps...)
+		normalized.Tables = append(normalized.Tables, report.Tables...)
+		normalized.Failures = append(normalized.Failures, report.Failures...)
+	}
+	payload, err := json.MarshalIndent(normalized, "", "  ")
+	if err != nil {
+		return fmt.Errorf("encode import report: %w", err)
+	}
+	if err := os.WriteFile(path, append(payload, '\n'), 0o644); err != nil {
+		return fmt.Errorf("write import report %s: %w", path, err)
+	}
+	return nil
+}
+
+// ImportSnapshotToPostgres bulk-loads a Snapshot into the postgresRepository.
+// The import runs in steps, one transaction per entity type, and records
+// each finished step so a re-run after a failure skips the steps already
+// done. Rows that already exist are left as they are. The report is
+// returned even when the import fails, covering the steps that committed.
+func ImportSnapshotToPostgres(ctx context.Context, repo Repository, snapshot *Snapshot, opts ...SnapshotImportOption) (*SnapshotImportReport, error) {
 	if snapshot == nil {
-		return fmt.Errorf("snapshot is required")
+		return nil, fmt.Errorf("snapshot is required")
 	}
 	pgRepo, ok := repo.(*postgresRepository)
 	if !ok {
-		return fmt.Errorf("postgres repository required for snapshot import")
+		return nil, fmt.Errorf("postgres repository required for snapshot import")
+	}
+	var cfg snapshotImportConfig
+	for _, opt := range opts {
+		if opt != nil {
+			opt(&cfg)
+		}
 	}
 	snapshot.ensureInitialized()
-	return pgRepo.importSnapshot(ctx, snapshot)
+	return pgRepo.importSnapshot(ctx, snapshot, cfg)
 }
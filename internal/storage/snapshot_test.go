@@ -0,0 +1,179 @@
+package storage
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+	"time"
+
+	"bitriver-live/internal/models"
+)
+
+func TestSaveSnapshotToJSONRoundTrip(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	snapshot := &Snapshot{
+		Users: map[string]models.User{
+			"user-1": {ID: "user-1", DisplayName: "Owner", Email: "owner@example.com", CreatedAt: now},
+		},
+		ChatMessages: map[string]models.ChatMessage{
+			"msg-1": {ID: "msg-1", ChannelID: "channel-1", UserID: "user-1", Content: "hello", CreatedAt: now},
+		},
+		Follows: map[string]map[string]time.Time{
+			"user-1": {"channel-1": now},
+		},
+		CoStreamInvites: map[string]models.CoStreamInvite{
+			"invite-1": {ID: "invite-1", SessionID: "session-1", HostChannelID: "channel-1", GuestChannelID: "channel-2", Status: models.CoStreamInviteAccepted, CreatedAt: now, RespondedAt: &now},
+		},
+		ChannelSlugRedirects: map[string]models.ChannelSlugRedirect{
+			"old-lobby": {Slug: "old-lobby", ChannelID: "channel-1", CreatedAt: now},
+		},
+		FeatureFlags: map[string]models.FeatureFlag{
+			"new-player": {Key: "new-player", Enabled: true, RolloutPercent: 25, AllowUserIDs: []string{"user-1"}, DenyUserIDs: []string{"user-2"}, CreatedAt: now, UpdatedAt: now},
+		},
+	}
+
+	for _, compress := range []bool{false, true} {
+		name := "plain"
+		if compress {
+			name = "gzip"
+		}
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "snapshot.json")
+			if err := SaveSnapshotToJSON(path, snapshot, compress); err != nil {
+				t.Fatalf("SaveSnapshotToJSON: %v", err)
+			}
+
+			raw, err := os.ReadFile(path)
+			if err != nil {
+				t.Fatalf("read snapshot: %v", err)
+			}
+			gzipped := bytes.HasPrefix(raw, []byte{0x1f, 0x8b})
+			if gzipped != compress {
+				t.Fatalf("expected gzip=%v output, got gzip=%v", compress, gzipped)
+			}
+
+			loaded, err := LoadSnapshotFromJSON(path)
+			if err != nil {
+				t.Fatalf("LoadSnapshotFromJSON: %v", err)
+			}
+			if loaded.Counts() != snapshot.Counts() {
+				t.Fatalf("expected counts %+v, got %+v", snapshot.Counts(), loaded.Counts())
+			}
+			if got := loaded.ChatMessages["msg-1"].Content; got != "hello" {
+				t.Fatalf("expected chat content to round-trip, got %q", got)
+			}
+			if got := loaded.Follows["user-1"]["channel-1"]; !got.Equal(now) {
+				t.Fatalf("expected follow timestamp %v, got %v", now, got)
+			}
+			if got := loaded.CoStreamInvites["invite-1"]; !reflect.DeepEqual(got, snapshot.CoStreamInvites["invite-1"]) {
+				t.Fatalf("expected co-stream invite to round-trip, got %+v", got)
+			}
+			if got := loaded.ChannelSlugRedirects["old-lobby"]; !reflect.DeepEqual(got, snapshot.ChannelSlugRedirects["old-lobby"]) {
+				t.Fatalf("expected channel slug redirect to round-trip, got %+v", got)
+			}
+			if got := loaded.FeatureFlags["new-player"]; !reflect.DeepEqual(got, snapshot.FeatureFlags["new-player"]) {
+				t.Fatalf("expected feature flag to round-trip, got %+v", got)
+			}
+		})
+	}
+}
+
+func TestSaveSnapshotToJSONLoadsAsDatastore(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	snapshot := &Snapshot{
+		Users: map[string]models.User{
+			"user-1": {ID: "user-1", DisplayName: "Owner", Email: "owner@example.com", CreatedAt: time.Now().UTC()},
+		},
+	}
+	if err := SaveSnapshotToJSON(path, snapshot, false); err != nil {
+		t.Fatalf("SaveSnapshotToJSON: %v", err)
+	}
+
+	store, err := NewStorage(path)
+	if err != nil {
+		t.Fatalf("NewStorage: %v", err)
+	}
+	if _, ok := store.GetUser("user-1"); !ok {
+		t.Fatal("expected exported snapshot to load as a JSON datastore")
+	}
+}
+
+func TestValidateSnapshotReportsConstraintViolations(t *testing.T) {
+	now := time.Date(2024, 3, 1, 12, 0, 0, 0, time.UTC)
+	snapshot := &Snapshot{
+		Users: map[string]models.User{
+			"user-1": {ID: "user-1", DisplayName: "Owner", Email: "owner@example.com", CreatedAt: now},
+			"user-2": {ID: "user-2", DisplayName: "Copy", Email: "owner@example.com", CreatedAt: now},
+		},
+		Channels: map[string]models.Channel{
+			"channel-1": {ID: "channel-1", OwnerID: "user-1", StreamKey: "key-1", CreatedAt: now},
+			"channel-2": {ID: "channel-2", OwnerID: "user-1", StreamKey: "key-1", PlaybackRestriction: "friends", CreatedAt: now},
+		},
+		Follows: map[string]map[string]time.Time{
+			"user-1": {"channel-1": now, "channel-9": now},
+		},
+		ChatMessages: map[string]models.ChatMessage{
+			"msg-1": {ID: "msg-1", ChannelID: "channel-1", UserID: "user-9", Content: "hello", CreatedAt: now},
+		},
+		BadgeGrants: map[string]map[string]models.BadgeGrant{
+			"user-1": {"verified": {UserID: "user-1", Slug: "verified", GrantedAt: now}},
+		},
+	}
+
+	got := ValidateSnapshot(snapshot)
+	want := []SnapshotRowIssue{
+		{Table: "users", Key: "user-2", Problem: "email owner@example.com is already used by user user-1"},
+		{Table: "channels", Key: "channel-2", Problem: "stream key is already used by channel channel-1"},
+		{Table: "channels", Key: "channel-2", Problem: `playback_restriction "friends" is not one of followers or subscribers`},
+		{Table: "follows", Key: "user-1/channel-9", Problem: "channel_id references missing channel-9"},
+		{Table: "chat_messages", Key: "msg-1", Problem: "user_id references missing user-9"},
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Fatalf("unexpected violations:\n got %+v\nwant %+v", got, want)
+	}
+
+	delete(snapshot.Users, "user-2")
+	delete(snapshot.Channels, "channel-2")
+	delete(snapshot.Follows["user-1"], "channel-9")
+	msg := snapshot.ChatMessages["msg-1"]
+	msg.UserID = "user-1"
+	snapshot.ChatMessages["msg-1"] = msg
+	if got := ValidateSnapshot(snapshot); len(got) != 0 {
+		t.Fatalf("expected a clean snapshot after the fixes, got %+v", got)
+	}
+}
+
+func TestSaveSnapshotImportReport(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "report.json")
+	report := &SnapshotImportReport{
+		DryRun:     true,
+		Violations: []SnapshotRowIssue{{Table: "follows", Key: "user-1/channel-9", Problem: "channel_id references missing channel-9"}},
+	}
+	if err := SaveSnapshotImportReport(path, report); err != nil {
+		t.Fatalf("SaveSnapshotImportReport: %v", err)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("read report: %v", err)
+	}
+	var decoded map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &decoded); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	for _, field := range []string{"steps", "tables", "failures"} {
+		if string(decoded[field]) != "[]" {
+			t.Fatalf("expected %s to be an empty list, got %s", field, decoded[field])
+		}
+	}
+	var loaded SnapshotImportReport
+	if err := json.Unmarshal(raw, &loaded); err != nil {
+		t.Fatalf("decode report: %v", err)
+	}
+	if !loaded.DryRun || !reflect.DeepEqual(loaded.Violations, report.Violations) {
+		t.Fatalf("unexpected report %+v", loaded)
+	}
+}
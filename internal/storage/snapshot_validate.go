@@ -0,0 +1,520 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/chat"
+	"bitriver-live/internal/models"
+)
+
+// SnapshotRowIssue describes a snapshot row that Postgres would reject, or
+// did reject during an import. Key identifies the row within Table, using
+// slash-separated columns for composite keys.
+type SnapshotRowIssue struct {
+	Table   string `json:"table"`
+	Key     string `json:"key"`
+	Problem string `json:"problem"`
+}
+
+// ValidateSnapshot checks the snapshot against the constraints of the
+// Postgres schema: unique emails, stream keys, and provider references,
+// foreign keys between collections, and the values the schema's CHECK
+// constraints allow. It treats the snapshot as the whole dataset, so rows
+// that reference records already in the target database are reported too.
+// The issues come back in import order.
+func ValidateSnapshot(snapshot *Snapshot) []SnapshotRowIssue {
+	if snapshot == nil {
+		return nil
+	}
+	snapshot.ensureInitialized()
+	v := &snapshotValidator{snapshot: snapshot, issues: make([]SnapshotRowIssue, 0)}
+	v.collectIDs()
+	v.users()
+	v.profiles()
+	v.channels()
+	v.channelSlugRedirects()
+	v.follows()
+	v.channelEditors()
+	v.streamSessions()
+	v.streamMarkers()
+	v.restreamTargets()
+	v.recordings()
+	v.uploads()
+	v.clipExports()
+	v.chatMessages()
+	v.chatModeration()
+	v.chatReports()
+	v.chatAppeals()
+	v.moderationActions()
+	v.tips()
+	v.subscriptions()
+	v.oauthAccounts()
+	v.apiTokens()
+	v.preferences()
+	v.badgeGrants()
+	v.schedules()
+	v.channelStorage()
+	v.chatPins()
+	v.chatSequences()
+	v.channelModerators()
+	v.securityEvents()
+	v.notifications()
+	v.coStreamInvites()
+	v.featureFlags()
+	return v.issues
+}
+
+type snapshotValidator struct {
+	snapshot *Snapshot
+	issues   []SnapshotRowIssue
+
+	userIDs      map[string]struct{}
+	channelIDs   map[string]struct{}
+	sessionIDs   map[string]struct{}
+	recordingIDs map[string]struct{}
+	messageIDs   map[string]struct{}
+	badgeSlugs   map[string]struct{}
+}
+
+// snapshotRowID returns the ID the importer writes for a row: the row's own
+// ID, or the map key when the row has none.
+func snapshotRowID(id, key string) string {
+	if trimmed := strings.TrimSpace(id); trimmed != "" {
+		return trimmed
+	}
+	return key
+}
+
+func sortedSnapshotKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func (v *snapshotValidator) report(table, key, format string, args ...any) {
+	v.issues = append(v.issues, SnapshotRowIssue{Table: table, Key: key, Problem: fmt.Sprintf(format, args...)})
+}
+
+// require reports a row whose column references a missing record. Optional
+// references are skipped when empty.
+func (v *snapshotValidator) require(table, key, column, id string, known map[string]struct{}, optional bool) {
+	id = strings.TrimSpace(id)
+	if id == "" {
+		if !optional {
+			v.report(table, key, "%s is required", column)
+		}
+		return
+	}
+	if _, ok := known[id]; !ok {
+		v.report(table, key, "%s references missing %s", column, id)
+	}
+}
+
+func (v *snapshotValidator) collectIDs() {
+	s := v.snapshot
+	v.userIDs = make(map[string]struct{}, len(s.Users))
+	for key, user := range s.Users {
+		v.userIDs[snapshotRowID(user.ID, key)] = struct{}{}
+	}
+	v.channelIDs = make(map[string]struct{}, len(s.Channels))
+	for key, channel := range s.Channels {
+		v.channelIDs[snapshotRowID(channel.ID, key)] = struct{}{}
+	}
+	v.sessionIDs = make(map[string]struct{}, len(s.StreamSessions))
+	for key, session := range s.StreamSessions {
+		v.sessionIDs[snapshotRowID(session.ID, key)] = struct{}{}
+	}
+	v.recordingIDs = make(map[string]struct{}, len(s.Recordings))
+	for key, recording := range s.Recordings {
+		v.recordingIDs[snapshotRowID(recording.ID, key)] = struct{}{}
+	}
+	v.messageIDs = make(map[string]struct{}, len(s.ChatMessages))
+	for key, message := range s.ChatMessages {
+		v.messageIDs[snapshotRowID(message.ID, key)] = struct{}{}
+	}
+	// The schema migration seeds the default badges, so grants of them hold
+	// even when the snapshot predates badge definitions.
+	v.badgeSlugs = make(map[string]struct{})
+	for slug := range defaultBadgeDefinitions(time.Time{}) {
+		v.badgeSlugs[slug] = struct{}{}
+	}
+	for slug := range s.BadgeDefinitions {
+		v.badgeSlugs[slug] = struct{}{}
+	}
+}
+
+func (v *snapshotValidator) users() {
+	emails := make(map[string]string, len(v.snapshot.Users))
+	usernames := make(map[string]string, len(v.snapshot.Users))
+	for _, key := range sortedSnapshotKeys(v.snapshot.Users) {
+		user := v.snapshot.Users[key]
+		id := snapshotRowID(user.ID, key)
+		email := strings.TrimSpace(user.Email)
+		if email == "" {
+			v.report("users", id, "email is required")
+			continue
+		}
+		if other, taken := emails[email]; taken {
+			v.report("users", id, "email %s is already used by user %s", email, other)
+			continue
+		}
+		emails[email] = id
+		// Users without a username are assigned one during the import.
+		if username := usernameKey(user.Username); username != "" {
+			if other, taken := usernames[username]; taken {
+				v.report("users", id, "username %s is already used by user %s", user.Username, other)
+				continue
+			}
+			usernames[username] = id
+		}
+	}
+}
+
+func (v *snapshotValidator) profiles() {
+	for _, userID := range sortedSnapshotKeys(v.snapshot.Profiles) {
+		v.require("profiles", userID, "user_id", userID, v.userIDs, false)
+	}
+}
+
+func (v *snapshotValidator) channels() {
+	hashes := make(map[string]string, len(v.snapshot.Channels))
+	for _, key := range sortedSnapshotKeys(v.snapshot.Channels) {
+		channel := v.snapshot.Channels[key]
+		id := snapshotRowID(channel.ID, key)
+		v.require("channels", id, "owner_id", channel.OwnerID, v.userIDs, false)
+		hash := strings.TrimSpace(channel.StreamKeyHash)
+		if plaintext := strings.TrimSpace(channel.StreamKey); plaintext != "" && hash == "" {
+			hash = HashStreamKey(plaintext)
+		}
+		if hash == "" {
+			v.report("channels", id, "channel has no stream key")
+		} else if other, taken := hashes[hash]; taken {
+			v.report("channels", id, "stream key is already used by channel %s", other)
+		} else {
+			hashes[hash] = id
+		}
+		switch restriction := strings.TrimSpace(channel.PlaybackRestriction); restriction {
+		case "", models.PlaybackRestrictionFollowers, models.PlaybackRestrictionSubscribers:
+		default:
+			v.report("channels", id, "playback_restriction %q is not one of followers or subscribers", restriction)
+		}
+		if _, err := normalizeRecordingPolicy(channel.RecordingPolicy); err != nil {
+			v.report("channels", id, "%v", err)
+		}
+		if _, err := normalizeChannelLanguage(channel.Language); err != nil {
+			v.report("channels", id, "%v", err)
+		}
+		if _, err := normalizeContentWarnings(channel.ContentWarnings); err != nil {
+			v.report("channels", id, "%v", err)
+		}
+		if _, err := chat.NormalizeMaxCapsPercent(channel.ChatMaxCapsPercent); err != nil {
+			v.report("channels", id, "%v", err)
+		}
+		if _, err := chat.NormalizeWelcomeMessage(channel.ChatWelcomeMessage); err != nil {
+			v.report("channels", id, "%v", err)
+		}
+	}
+}
+
+func (v *snapshotValidator) follows() {
+	for _, userID := range sortedSnapshotKeys(v.snapshot.Follows) {
+		for _, channelID := range sortedSnapshotKeys(v.snapshot.Follows[userID]) {
+			key := userID + "/" + channelID
+			v.require("follows", key, "user_id", userID, v.userIDs, false)
+			v.require("follows", key, "channel_id", channelID, v.channelIDs, false)
+		}
+	}
+}
+
+func (v *snapshotValidator) channelEditors() {
+	for _, channelID := range sortedSnapshotKeys(v.snapshot.ChannelEditors) {
+		editors := v.snapshot.ChannelEditors[channelID]
+		for _, userID := range sortedSnapshotKeys(editors) {
+			key := channelID + "/" + userID
+			v.require("channel_editors", key, "channel_id", channelID, v.channelIDs, false)
+			v.require("channel_editors", key, "user_id", userID, v.userIDs, false)
+			v.require("channel_editors", key, "granted_by", editors[userID].GrantedBy, v.userIDs, true)
+		}
+	}
+}
+
+func (v *snapshotValidator) streamSessions() {
+	for _, key := range sortedSnapshotKeys(v.snapshot.StreamSessions) {
+		session := v.snapshot.StreamSessions[key]
+		v.require("stream_sessions", snapshotRowID(session.ID, key), "channel_id", session.ChannelID, v.channelIDs, false)
+	}
+}
+
+func (v *snapshotValidator) streamMarkers() {
+	for _, key := range sortedSnapshotKeys(v.snapshot.StreamMarkers) {
+		marker := v.snapshot.StreamMarkers[key]
+		v.require("stream_markers", key, "channel_id", marker.ChannelID, v.channelIDs, false)
+		v.require("stream_markers", key, "session_id", marker.SessionID, v.sessionIDs, false)
+		v.require("stream_markers", key, "created_by", marker.CreatedBy, v.userIDs, true)
+		if marker.OffsetSeconds < 0 {
+			v.report("stream_markers", key, "offset_seconds %d is negative", marker.OffsetSeconds)
+		}
+	}
+}
+
+func (v *snapshotValidator) restreamTargets() {
+	for _, key := range sortedSnapshotKeys(v.snapshot.RestreamTargets) {
+		v.require("restream_targets", key, "channel_id", v.snapshot.RestreamTargets[key].ChannelID, v.channelIDs, false)
+	}
+}
+
+func (v *snapshotValidator) recordings() {
+	for _, key := range sortedSnapshotKeys(v.snapshot.Recordings) {
+		recording := v.snapshot.Recordings[key]
+		id := snapshotRowID(recording.ID, key)
+		v.require("recordings", id, "channel_id", recording.ChannelID, v.channelIDs, false)
+		v.require("recordings", id, "session_id", recording.SessionID, v.sessionIDs, false)
+	}
+}
+
+func (v *snapshotValidator) uploads() {
+	for _, key := range sortedSnapshotKeys(v.snapshot.Uploads) {
+		upload := v.snapshot.Uploads[key]
+		v.require("uploads", snapshotRowID(upload.ID, key), "channel_id", upload.ChannelID, v.channelIDs, false)
+	}
+}
+
+func (v *snapshotValidator) clipExports() {
+	for _, key := range sortedSnapshotKeys(v.snapshot.ClipExports) {
+		clip := v.snapshot.ClipExports[key]
+		id := snapshotRowID(clip.ID, key)
+		v.require("clip_exports", id, "channel_id", clip.ChannelID, v.channelIDs, false)
+		v.require("clip_exports", id, "session_id", clip.SessionID, v.sessionIDs, false)
+		v.require("clip_exports", id, "recording_id", clip.RecordingID, v.recordingIDs, true)
+		v.require("clip_exports", id, "created_by", clip.CreatedBy, v.userIDs, true)
+	}
+}
+
+func (v *snapshotValidator) chatMessages() {
+	for _, key := range sortedSnapshotKeys(v.snapshot.ChatMessages) {
+		message := v.snapshot.ChatMessages[key]
+		id := snapshotRowID(message.ID, key)
+		v.require("chat_messages", id, "channel_id", message.ChannelID, v.channelIDs, false)
+		v.require("chat_messages", id, "user_id", message.UserID, v.userIDs, false)
+	}
+}
+
+func (v *snapshotValidator) chatModeration() {
+	v.chatRestrictions("chat_bans", v.snapshot.ChatBans, v.snapshot.ChatBanActors)
+	v.chatRestrictions("chat_timeouts", v.snapshot.ChatTimeouts, v.snapshot.ChatTimeoutActors)
+}
+
+func (v *snapshotValidator) chatRestrictions(table string, entries map[string]map[string]time.Time, actors map[string]map[string]string) {
+	for _, channelID := range sortedSnapshotKeys(entries) {
+		for _, userID := range sortedSnapshotKeys(entries[channelID]) {
+			key := channelID + "/" + userID
+			v.require(table, key, "channel_id", channelID, v.channelIDs, false)
+			v.require(table, key, "user_id", userID, v.userIDs, false)
+			v.require(table, key, "actor_id", lookupString(actors, channelID, userID), v.userIDs, true)
+		}
+	}
+}
+
+func (v *snapshotValidator) chatReports() {
+	for _, key := range sortedSnapshotKeys(v.snapshot.ChatReports) {
+		report := v.snapshot.ChatReports[key]
+		id := snapshotRowID(report.ID, key)
+		v.require("chat_reports", id, "channel_id", report.ChannelID, v.channelIDs, false)
+		v.require("chat_reports", id, "reporter_id", report.ReporterID, v.userIDs, false)
+		v.require("chat_reports", id, "target_id", report.TargetID, v.userIDs, false)
+		v.require("chat_reports", id, "message_id", report.MessageID, v.messageIDs, true)
+		v.require("chat_reports", id, "resolver_id", report.ResolverID, v.userIDs, true)
+	}
+}
+
+func (v *snapshotValidator) chatAppeals() {
+	open := make(map[string]string)
+	for _, key := range sortedSnapshotKeys(v.snapshot.ChatAppeals) {
+		appeal := v.snapshot.ChatAppeals[key]
+		v.require("chat_appeals", key, "channel_id", appeal.ChannelID, v.channelIDs, false)
+		v.require("chat_appeals", key, "user_id", appeal.UserID, v.userIDs, false)
+		v.require("chat_appeals", key, "resolver_id", appeal.ResolverID, v.userIDs, true)
+		if appeal.Status != ChatAppealStatusOpen {
+			continue
+		}
+		viewer := appeal.ChannelID + "/" + appeal.UserID
+		if other, taken := open[viewer]; taken {
+			v.report("chat_appeals", key, "user already has open appeal %s in the channel", other)
+			continue
+		}
+		open[viewer] = key
+	}
+}
+
+func (v *snapshotValidator) moderationActions() {
+	for _, id := range sortedSnapshotKeys(v.snapshot.ModerationActions) {
+		action := v.snapshot.ModerationActions[id]
+		v.require("moderation_actions", id, "channel_id", action.ChannelID, v.channelIDs, false)
+		v.require("moderation_actions", id, "target_id", action.TargetID, v.userIDs, false)
+		v.require("moderation_actions", id, "actor_id", action.ActorID, v.userIDs, true)
+	}
+}
+
+func (v *snapshotValidator) tips() {
+	references := make(map[string]string)
+	for _, key := range sortedSnapshotKeys(v.snapshot.Tips) {
+		tip := v.snapshot.Tips[key]
+		id := snapshotRowID(tip.ID, key)
+		v.require("tips", id, "channel_id", tip.ChannelID, v.channelIDs, false)
+		v.require("tips", id, "from_user_id", tip.FromUserID, v.userIDs, false)
+		reference := strings.TrimSpace(tip.Provider) + "/" + strings.TrimSpace(tip.Reference)
+		if other, taken := references[reference]; taken {
+			v.report("tips", id, "provider reference %s is already used by tip %s", reference, other)
+			continue
+		}
+		references[reference] = id
+	}
+}
+
+func (v *snapshotValidator) subscriptions() {
+	references := make(map[string]string)
+	for _, key := range sortedSnapshotKeys(v.snapshot.Subscriptions) {
+		sub := v.snapshot.Subscriptions[key]
+		id := snapshotRowID(sub.ID, key)
+		v.require("subscriptions", id, "channel_id", sub.ChannelID, v.channelIDs, false)
+		v.require("subscriptions", id, "user_id", sub.UserID, v.userIDs, false)
+		v.require("subscriptions", id, "cancelled_by", sub.CancelledBy, v.userIDs, true)
+		v.require("subscriptions", id, "gifter_id", sub.GifterID, v.userIDs, true)
+		reference := strings.TrimSpace(sub.Provider) + "/" + strings.TrimSpace(sub.Reference)
+		if other, taken := references[reference]; taken {
+			v.report("subscriptions", id, "provider reference %s is already used by subscription %s", reference, other)
+			continue
+		}
+		references[reference] = id
+	}
+}
+
+func (v *snapshotValidator) oauthAccounts() {
+	for _, key := range sortedSnapshotKeys(v.snapshot.OAuthAccounts) {
+		v.require("oauth_accounts", key, "user_id", v.snapshot.OAuthAccounts[key].UserID, v.userIDs, false)
+	}
+}
+
+func (v *snapshotValidator) apiTokens() {
+	hashes := make(map[string]string)
+	for _, id := range sortedSnapshotKeys(v.snapshot.APITokens) {
+		token := v.snapshot.APITokens[id]
+		v.require("api_tokens", id, "user_id", token.UserID, v.userIDs, false)
+		if other, taken := hashes[token.TokenHash]; taken {
+			v.report("api_tokens", id, "token hash is already used by token %s", other)
+			continue
+		}
+		hashes[token.TokenHash] = id
+	}
+}
+
+func (v *snapshotValidator) preferences() {
+	for _, userID := range sortedSnapshotKeys(v.snapshot.NotificationPreferences) {
+		v.require("notification_preferences", userID, "user_id", userID, v.userIDs, false)
+	}
+	for _, userID := range sortedSnapshotKeys(v.snapshot.PlaybackPreferences) {
+		v.require("playback_preferences", userID, "user_id", userID, v.userIDs, false)
+	}
+}
+
+func (v *snapshotValidator) badgeGrants() {
+	for _, userID := range sortedSnapshotKeys(v.snapshot.BadgeGrants) {
+		for _, slug := range sortedSnapshotKeys(v.snapshot.BadgeGrants[userID]) {
+			key := userID + "/" + slug
+			v.require("user_badges", key, "user_id", userID, v.userIDs, false)
+			v.require("user_badges", key, "badge_slug", slug, v.badgeSlugs, false)
+		}
+	}
+}
+
+func (v *snapshotValidator) schedules() {
+	for _, key := range sortedSnapshotKeys(v.snapshot.ScheduleEntries) {
+		v.require("schedule_entries", key, "channel_id", v.snapshot.ScheduleEntries[key].ChannelID, v.channelIDs, false)
+	}
+	for _, userID := range sortedSnapshotKeys(v.snapshot.ScheduleFeedTokens) {
+		v.require("schedule_feed_tokens", userID, "user_id", userID, v.userIDs, false)
+	}
+}
+
+func (v *snapshotValidator) channelStorage() {
+	for _, channelID := range sortedSnapshotKeys(v.snapshot.ChannelStorage) {
+		v.require("channel_storage", channelID, "channel_id", channelID, v.channelIDs, false)
+	}
+}
+
+func (v *snapshotValidator) chatPins() {
+	for _, channelID := range sortedSnapshotKeys(v.snapshot.ChatPins) {
+		pin := v.snapshot.ChatPins[channelID]
+		v.require("chat_pins", channelID, "channel_id", channelID, v.channelIDs, false)
+		v.require("chat_pins", channelID, "author_id", pin.AuthorID, v.userIDs, true)
+		v.require("chat_pins", channelID, "pinned_by", pin.PinnedBy, v.userIDs, true)
+	}
+}
+
+func (v *snapshotValidator) channelModerators() {
+	for _, channelID := range sortedSnapshotKeys(v.snapshot.ChannelModerators) {
+		moderators := v.snapshot.ChannelModerators[channelID]
+		for _, userID := range sortedSnapshotKeys(moderators) {
+			key := channelID + "/" + userID
+			v.require("channel_moderators", key, "channel_id", channelID, v.channelIDs, false)
+			v.require("channel_moderators", key, "user_id", userID, v.userIDs, false)
+			v.require("channel_moderators", key, "granted_by", moderators[userID].GrantedBy, v.userIDs, true)
+		}
+	}
+}
+
+func (v *snapshotValidator) chatSequences() {
+	for _, channelID := range sortedSnapshotKeys(v.snapshot.ChatSequences) {
+		v.require("chat_sequences", channelID, "channel_id", channelID, v.channelIDs, false)
+	}
+}
+
+func (v *snapshotValidator) securityEvents() {
+	for _, id := range sortedSnapshotKeys(v.snapshot.SecurityEvents) {
+		v.require("security_events", id, "channel_id", v.snapshot.SecurityEvents[id].ChannelID, v.channelIDs, false)
+	}
+}
+
+func (v *snapshotValidator) notifications() {
+	for _, id := range sortedSnapshotKeys(v.snapshot.Notifications) {
+		v.require("notifications", id, "user_id", v.snapshot.Notifications[id].UserID, v.userIDs, false)
+	}
+}
+
+func (v *snapshotValidator) coStreamInvites() {
+	open := make(map[string]string)
+	for _, id := range sortedSnapshotKeys(v.snapshot.CoStreamInvites) {
+		invite := v.snapshot.CoStreamInvites[id]
+		if invite.Active() {
+			guest := strings.TrimSpace(invite.SessionID) + "/" + strings.TrimSpace(invite.GuestChannelID)
+			if first, ok := open[guest]; ok {
+				v.report("costream_invites", id, "guest %s already has open invite %s into session %s", invite.GuestChannelID, first, invite.SessionID)
+			} else {
+				open[guest] = id
+			}
+		}
+		v.require("costream_invites", id, "session_id", invite.SessionID, v.sessionIDs, false)
+		v.require("costream_invites", id, "host_channel_id", invite.HostChannelID, v.channelIDs, false)
+		v.require("costream_invites", id, "guest_channel_id", invite.GuestChannelID, v.channelIDs, false)
+	}
+}
+
+func (v *snapshotValidator) featureFlags() {
+	for _, key := range sortedSnapshotKeys(v.snapshot.FeatureFlags) {
+		if percent := v.snapshot.FeatureFlags[key].RolloutPercent; percent < 0 || percent > 100 {
+			v.report("feature_flags", key, "rollout_percent %d is not between 0 and 100", percent)
+		}
+	}
+}
+
+func (v *snapshotValidator) channelSlugRedirects() {
+	for _, slug := range sortedSnapshotKeys(v.snapshot.ChannelSlugRedirects) {
+		v.require("channel_slug_redirects", slug, "channel_id", v.snapshot.ChannelSlugRedirects[slug].ChannelID, v.channelIDs, false)
+	}
+}
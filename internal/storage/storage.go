@@ -3,6 +3,7 @@ package storage
 import (
 	"context"
 	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -14,6 +15,7 @@ import (
 	"strings"
 	"time"
 
+	"bitriver-live/internal/chat"
 	"bitriver-live/internal/ingest"
 	"bitriver-live/internal/models"
 )
@@ -27,14 +29,37 @@ func newDataset() dataset {
 	ds := dataset{
 		Users:          make(map[string]models.User),
 		OAuthAccounts:  make(map[string]models.OAuthAccount),
+		APITokens:      make(map[string]models.APIToken),
 		Channels:       make(map[string]models.Channel),
 		StreamSessions: make(map[string]models.StreamSession),
 		Tips:           make(map[string]models.Tip),
 		Subscriptions:  make(map[string]models.Subscription),
 		Profiles:       make(map[string]models.Profile),
 		Follows:        make(map[string]map[string]time.Time),
+		ChannelEditors: make(map[string]map[string]models.ChannelEditor),
 		Recordings:     make(map[string]models.Recording),
 		ClipExports:    make(map[string]models.ClipExport),
+		Jobs:           make(map[string]Job),
+
+		NotificationPreferences: make(map[string]models.NotificationPreferences),
+		StreamMarkers:           make(map[string]models.StreamMarker),
+		RestreamTargets:         make(map[string]models.RestreamTarget),
+		ChatAppeals:             make(map[string]models.ChatAppeal),
+		PlaybackPreferences:     make(map[string]models.PlaybackPreferences),
+		BadgeDefinitions:        defaultBadgeDefinitions(time.Now().UTC()),
+		BadgeGrants:             make(map[string]map[string]models.BadgeGrant),
+		ScheduleEntries:         make(map[string]models.ScheduleEntry),
+		ScheduleFeedTokens:      make(map[string]models.ScheduleFeedToken),
+		ChannelStorage:          make(map[string]models.ChannelStorage),
+		ChatPins:                make(map[string]models.ChatPin),
+		ChannelModerators:       make(map[string]map[string]models.ChannelModerator),
+		ChatSequences:           make(map[string]int64),
+		PlatformStats:           make(map[string]PlatformStats),
+		SecurityEvents:          make(map[string]models.SecurityEvent),
+		Notifications:           make(map[string]models.Notification),
+		CoStreamInvites:         make(map[string]models.CoStreamInvite),
+		FeatureFlags:            make(map[string]models.FeatureFlag),
+		ChannelSlugRedirects:    make(map[string]models.ChannelSlugRedirect),
 	}
 	initChatDataset(&ds)
 	return ds
@@ -47,6 +72,9 @@ func (s *Storage) ensureDatasetInitializedLocked() {
 	if s.data.OAuthAccounts == nil {
 		s.data.OAuthAccounts = make(map[string]models.OAuthAccount)
 	}
+	if s.data.APITokens == nil {
+		s.data.APITokens = make(map[string]models.APIToken)
+	}
 	if s.data.Channels == nil {
 		s.data.Channels = make(map[string]models.Channel)
 	}
@@ -66,6 +94,12 @@ func (s *Storage) ensureDatasetInitializedLocked() {
 	if s.data.Follows == nil {
 		s.data.Follows = make(map[string]map[string]time.Time)
 	}
+	if s.data.ChannelEditors == nil {
+		s.data.ChannelEditors = make(map[string]map[string]models.ChannelEditor)
+	}
+	if s.data.ChannelModerators == nil {
+		s.data.ChannelModerators = make(map[string]map[string]models.ChannelModerator)
+	}
 	if s.data.Recordings == nil {
 		s.data.Recordings = make(map[string]models.Recording)
 	}
@@ -75,6 +109,65 @@ func (s *Storage) ensureDatasetInitializedLocked() {
 	if s.data.ClipExports == nil {
 		s.data.ClipExports = make(map[string]models.ClipExport)
 	}
+	if s.data.Jobs == nil {
+		s.data.Jobs = make(map[string]Job)
+	}
+	if s.data.NotificationPreferences == nil {
+		s.data.NotificationPreferences = make(map[string]models.NotificationPreferences)
+	}
+	if s.data.StreamMarkers == nil {
+		s.data.StreamMarkers = make(map[string]models.StreamMarker)
+	}
+	if s.data.RestreamTargets == nil {
+		s.data.RestreamTargets = make(map[string]models.RestreamTarget)
+	}
+	if s.data.ChatAppeals == nil {
+		s.data.ChatAppeals = make(map[string]models.ChatAppeal)
+	}
+	if s.data.PlaybackPreferences == nil {
+		s.data.PlaybackPreferences = make(map[string]models.PlaybackPreferences)
+	}
+	// Datasets written before global badges existed get the default
+	// definitions once; an empty map means an administrator removed them.
+	if s.data.BadgeDefinitions == nil {
+		s.data.BadgeDefinitions = defaultBadgeDefinitions(time.Now().UTC())
+	}
+	if s.data.BadgeGrants == nil {
+		s.data.BadgeGrants = make(map[string]map[string]models.BadgeGrant)
+	}
+	if s.data.ScheduleEntries == nil {
+		s.data.ScheduleEntries = make(map[string]models.ScheduleEntry)
+	}
+	if s.data.ScheduleFeedTokens == nil {
+		s.data.ScheduleFeedTokens = make(map[string]models.ScheduleFeedToken)
+	}
+	if s.data.ChannelStorage == nil {
+		s.data.ChannelStorage = rebuildChannelStorage(s.data, time.Now().UTC())
+	}
+	if s.data.ChatPins == nil {
+		s.data.ChatPins = make(map[string]models.ChatPin)
+	}
+	if s.data.ChatSequences == nil {
+		s.data.ChatSequences = make(map[string]int64)
+	}
+	if s.data.PlatformStats == nil {
+		s.data.PlatformStats = make(map[string]PlatformStats)
+	}
+	if s.data.SecurityEvents == nil {
+		s.data.SecurityEvents = make(map[string]models.SecurityEvent)
+	}
+	if s.data.Notifications == nil {
+		s.data.Notifications = make(map[string]models.Notification)
+	}
+	if s.data.CoStreamInvites == nil {
+		s.data.CoStreamInvites = make(map[string]models.CoStreamInvite)
+	}
+	if s.data.FeatureFlags == nil {
+		s.data.FeatureFlags = make(map[string]models.FeatureFlag)
+	}
+	if s.data.ChannelSlugRedirects == nil {
+		s.data.ChannelSlugRedirects = make(map[string]models.ChannelSlugRedirect)
+	}
 }
 
 func buildObjectKey(parts ...string) string {
@@ -221,6 +314,7 @@ func NewStorage(path string, opts ...Option) (*Storage, error) {
 		ingestController:    ingest.NoopController{},
 		ingestMaxAttempts:   1,
 		ingestTimeout:       defaultIngestOperationTimeout,
+		startingTimeout:     defaultStartingTimeout,
 		ingestHealth:        []ingest.HealthStatus{{Component: "ingest", Status: "disabled"}},
 		ingestHealthUpdated: time.Now().UTC(),
 		recordingRetention: RecordingRetentionPolicy{
@@ -229,6 +323,7 @@ func NewStorage(path string, opts ...Option) (*Storage, error) {
 		},
 		objectClient: noopObjectStorageClient{},
 		retentionNow: func() time.Time { return time.Now().UTC() },
+		grantNow:     func() time.Time { return time.Now().UTC() },
 	}
 	for _, opt := range opts {
 		if opt != nil {
@@ -242,6 +337,12 @@ func NewStorage(path string, opts ...Option) (*Storage, error) {
 		store.ingestMaxAttempts = 1
 	}
 	store.ingestTimeout = normalizeIngestTimeout(store.ingestTimeout)
+	store.startingTimeout = normalizeStartingTimeout(store.startingTimeout)
+	secrets, err := newSecretKeyring(store.secretKey, store.encryptionKeys)
+	if err != nil {
+		return nil, err
+	}
+	store.secrets = secrets
 	if err := store.load(); err != nil {
 		return nil, err
 	}
@@ -254,33 +355,66 @@ func (s *Storage) load() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
+	data, err := s.readDatasetFile()
+	if err != nil {
+		return err
+	}
+	s.data = data
+	s.ensureDatasetInitializedLocked()
+
+	if err := openDatasetFields(s.secrets, &s.data); err != nil {
+		return fmt.Errorf("open store file: %w", err)
+	}
+	s.rebuildChatClientMessagesLocked(time.Now().UTC())
+	for id, report := range s.data.ChatReports {
+		s.data.ChatReports[id] = withReportDefaults(report)
+	}
+
+	if hashPlaintextStreamKeys(s.data.Channels) {
+		if err := s.persist(); err != nil {
+			return fmt.Errorf("persist hashed stream keys: %w", err)
+		}
+	}
+	if backfillUsernames(s.data.Users) {
+		if err := s.persist(); err != nil {
+			return fmt.Errorf("persist backfilled usernames: %w", err)
+		}
+	}
+	if backfillChannelSlugs(s.data.Channels, s.data.ChannelSlugRedirects) {
+		if err := s.persist(); err != nil {
+			return fmt.Errorf("persist backfilled channel slugs: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// readDatasetFile decodes the datastore file as stored, with sensitive fields
+// still sealed. A missing or empty file yields an empty dataset.
+func (s *Storage) readDatasetFile() (dataset, error) {
 	if err := os.MkdirAll(filepath.Dir(s.filePath), 0o755); err != nil {
-		return fmt.Errorf("create data dir: %w", err)
+		return dataset{}, fmt.Errorf("create data dir: %w", err)
 	}
 
 	file, err := os.Open(s.filePath)
 	if errors.Is(err, os.ErrNotExist) {
-		s.data = newDataset()
-		return nil
+		return newDataset(), nil
 	} else if err != nil {
-		return fmt.Errorf("open store file: %w", err)
+		return dataset{}, fmt.Errorf("open store file: %w", err)
 	}
 	defer func() {
 		_ = file.Close()
 	}()
 
+	var data dataset
 	decoder := json.NewDecoder(file)
-	if err := decoder.Decode(&s.data); err != nil {
+	if err := decoder.Decode(&data); err != nil {
 		if errors.Is(err, io.EOF) {
-			s.data = newDataset()
-			return nil
+			return newDataset(), nil
 		}
-		return fmt.Errorf("decode store file: %w", err)
+		return dataset{}, fmt.Errorf("decode store file: %w", err)
 	}
-
-	s.ensureDatasetInitializedLocked()
-
-	return nil
+	return data, nil
 }
 
 func (s *Storage) persist() error {
@@ -293,6 +427,10 @@ func (s *Storage) persistDataset(data dataset) error {
 			return err
 		}
 	}
+	data, err := sealDatasetFields(s.secrets, data)
+	if err != nil {
+		return err
+	}
 
 	dir := filepath.Dir(s.filePath)
 	if err := os.MkdirAll(dir, 0o755); err != nil {
@@ -352,6 +490,13 @@ func cloneDataset(src dataset) dataset {
 		}
 	}
 
+	if src.APITokens != nil {
+		clone.APITokens = make(map[string]models.APIToken, len(src.APITokens))
+		for id, token := range src.APITokens {
+			clone.APITokens[id] = cloneAPIToken(token)
+		}
+	}
+
 	if src.Channels != nil {
 		clone.Channels = make(map[string]models.Channel, len(src.Channels))
 		for id, channel := range src.Channels {
@@ -359,10 +504,14 @@ func cloneDataset(src dataset) dataset {
 			if channel.Tags != nil {
 				cloned.Tags = append([]string(nil), channel.Tags...)
 			}
+			if channel.ContentWarnings != nil {
+				cloned.ContentWarnings = append([]string(nil), channel.ContentWarnings...)
+			}
 			if channel.CurrentSessionID != nil {
 				current := *channel.CurrentSessionID
 				cloned.CurrentSessionID = &current
 			}
+			cloned.TranscodeLimits = cloneTranscodeLimits(channel.TranscodeLimits)
 			clone.Channels[id] = cloned
 		}
 	}
@@ -378,6 +527,8 @@ func cloneDataset(src dataset) dataset {
 				ended := *session.EndedAt
 				cloned.EndedAt = &ended
 			}
+			cloned.Settings = cloneStreamSettings(session.Settings)
+			cloned.PlaybackEndpoints = clonePlaybackEndpoints(session.PlaybackEndpoints)
 			clone.StreamSessions[id] = cloned
 		}
 	}
@@ -424,6 +575,31 @@ func cloneDataset(src dataset) dataset {
 		}
 	}
 
+	if src.StreamMarkers != nil {
+		clone.StreamMarkers = make(map[string]models.StreamMarker, len(src.StreamMarkers))
+		for id, marker := range src.StreamMarkers {
+			clone.StreamMarkers[id] = marker
+		}
+	}
+
+	if src.RestreamTargets != nil {
+		clone.RestreamTargets = make(map[string]models.RestreamTarget, len(src.RestreamTargets))
+		for id, target := range src.RestreamTargets {
+			clone.RestreamTargets[id] = target
+		}
+	}
+
+	if src.ChatAppeals != nil {
+		clone.ChatAppeals = make(map[string]models.ChatAppeal, len(src.ChatAppeals))
+		for id, appeal := range src.ChatAppeals {
+			if appeal.ResolvedAt != nil {
+				resolved := *appeal.ResolvedAt
+				appeal.ResolvedAt = &resolved
+			}
+			clone.ChatAppeals[id] = appeal
+		}
+	}
+
 	if src.Profiles != nil {
 		clone.Profiles = make(map[string]models.Profile, len(src.Profiles))
 		for id, profile := range src.Profiles {
@@ -460,6 +636,144 @@ func cloneDataset(src dataset) dataset {
 		}
 	}
 
+	if src.ChannelEditors != nil {
+		clone.ChannelEditors = make(map[string]map[string]models.ChannelEditor, len(src.ChannelEditors))
+		for channelID, editors := range src.ChannelEditors {
+			copied := make(map[string]models.ChannelEditor, len(editors))
+			for userID, editor := range editors {
+				copied[userID] = editor
+			}
+			clone.ChannelEditors[channelID] = copied
+		}
+	}
+
+	if src.ChannelModerators != nil {
+		clone.ChannelModerators = make(map[string]map[string]models.ChannelModerator, len(src.ChannelModerators))
+		for channelID, moderators := range src.ChannelModerators {
+			copied := make(map[string]models.ChannelModerator, len(moderators))
+			for userID, moderator := range moderators {
+				copied[userID] = moderator
+			}
+			clone.ChannelModerators[channelID] = copied
+		}
+	}
+
+	if src.Jobs != nil {
+		clone.Jobs = make(map[string]Job, len(src.Jobs))
+		for id, job := range src.Jobs {
+			clone.Jobs[id] = cloneJob(job)
+		}
+	}
+
+	if src.NotificationPreferences != nil {
+		clone.NotificationPreferences = make(map[string]models.NotificationPreferences, len(src.NotificationPreferences))
+		for userID, prefs := range src.NotificationPreferences {
+			clone.NotificationPreferences[userID] = cloneNotificationPreferences(prefs)
+		}
+	}
+
+	if src.PlaybackPreferences != nil {
+		clone.PlaybackPreferences = make(map[string]models.PlaybackPreferences, len(src.PlaybackPreferences))
+		for userID, prefs := range src.PlaybackPreferences {
+			clone.PlaybackPreferences[userID] = clonePlaybackPreferences(prefs)
+		}
+	}
+
+	if src.BadgeDefinitions != nil {
+		clone.BadgeDefinitions = make(map[string]models.BadgeDefinition, len(src.BadgeDefinitions))
+		for slug, definition := range src.BadgeDefinitions {
+			clone.BadgeDefinitions[slug] = definition
+		}
+	}
+
+	if src.BadgeGrants != nil {
+		clone.BadgeGrants = make(map[string]map[string]models.BadgeGrant, len(src.BadgeGrants))
+		for userID, grants := range src.BadgeGrants {
+			copied := make(map[string]models.BadgeGrant, len(grants))
+			for slug, grant := range grants {
+				copied[slug] = grant
+			}
+			clone.BadgeGrants[userID] = copied
+		}
+	}
+
+	if src.ScheduleEntries != nil {
+		clone.ScheduleEntries = make(map[string]models.ScheduleEntry, len(src.ScheduleEntries))
+		for id, entry := range src.ScheduleEntries {
+			clone.ScheduleEntries[id] = entry
+		}
+	}
+
+	if src.ScheduleFeedTokens != nil {
+		clone.ScheduleFeedTokens = make(map[string]models.ScheduleFeedToken, len(src.ScheduleFeedTokens))
+		for userID, token := range src.ScheduleFeedTokens {
+			clone.ScheduleFeedTokens[userID] = token
+		}
+	}
+
+	if src.ChannelStorage != nil {
+		clone.ChannelStorage = make(map[string]models.ChannelStorage, len(src.ChannelStorage))
+		for channelID, entry := range src.ChannelStorage {
+			clone.ChannelStorage[channelID] = cloneChannelStorage(entry)
+		}
+	}
+
+	if src.ChatPins != nil {
+		clone.ChatPins = make(map[string]models.ChatPin, len(src.ChatPins))
+		for channelID, pin := range src.ChatPins {
+			clone.ChatPins[channelID] = pin
+		}
+	}
+
+	if src.ChatSequences != nil {
+		clone.ChatSequences = make(map[string]int64, len(src.ChatSequences))
+		for channelID, seq := range src.ChatSequences {
+			clone.ChatSequences[channelID] = seq
+		}
+	}
+
+	if src.PlatformStats != nil {
+		clone.PlatformStats = make(map[string]PlatformStats, len(src.PlatformStats))
+		for day, stats := range src.PlatformStats {
+			clone.PlatformStats[day] = stats
+		}
+	}
+
+	if src.SecurityEvents != nil {
+		clone.SecurityEvents = make(map[string]models.SecurityEvent, len(src.SecurityEvents))
+		for id, event := range src.SecurityEvents {
+			clone.SecurityEvents[id] = event
+		}
+	}
+
+	if src.Notifications != nil {
+		clone.Notifications = make(map[string]models.Notification, len(src.Notifications))
+		for id, notification := range src.Notifications {
+			clone.Notifications[id] = cloneNotification(notification)
+		}
+	}
+
+	if src.CoStreamInvites != nil {
+		clone.CoStreamInvites = make(map[string]models.CoStreamInvite, len(src.CoStreamInvites))
+		for id, invite := range src.CoStreamInvites {
+			clone.CoStreamInvites[id] = cloneCoStreamInvite(invite)
+		}
+	}
+
+	if src.FeatureFlags != nil {
+		clone.FeatureFlags = make(map[string]models.FeatureFlag, len(src.FeatureFlags))
+		for key, flag := range src.FeatureFlags {
+			clone.FeatureFlags[key] = cloneFeatureFlag(flag)
+		}
+	}
+
+	if src.ChannelSlugRedirects != nil {
+		clone.ChannelSlugRedirects = make(map[string]models.ChannelSlugRedirect, len(src.ChannelSlugRedirects))
+		for slug, redirect := range src.ChannelSlugRedirects {
+			clone.ChannelSlugRedirects[slug] = redirect
+		}
+	}
+
 	return clone
 }
 
@@ -471,29 +785,37 @@ func (s *Storage) CreateUser(params CreateUserParams) (models.User, error) {
 
 	normalizedEmail := strings.TrimSpace(strings.ToLower(params.Email))
 	if normalizedEmail == "" {
-		return models.User{}, errors.New("email is required")
+		return models.User{}, invalid("email", "email is required")
 	}
 	for _, user := range s.data.Users {
 		if user.Email == normalizedEmail {
-			return models.User{}, fmt.Errorf("email %s already in use", params.Email)
+			return models.User{}, conflict("email", "email %s already in use", params.Email)
 		}
 	}
 
 	displayName := strings.TrimSpace(params.DisplayName)
 	if displayName == "" {
-		return models.User{}, errors.New("displayName is required")
+		return models.User{}, invalid("displayName", "displayName is required")
 	}
 
 	roles := normalizeRoles(params.Roles)
 	if params.SelfSignup {
 		if params.Password == "" {
-			return models.User{}, errors.New("password is required for self-service signup")
+			return models.User{}, invalid("password", "password is required for self-service signup")
 		}
 		if len(roles) == 0 {
 			roles = []string{"viewer"}
 		}
 	}
 
+	now := time.Now().UTC()
+	username, usernameChangedAt, err := chooseUsername(params, now, func(key string) (bool, error) {
+		return usernameTaken(s.data.Users, key, ""), nil
+	})
+	if err != nil {
+		return models.User{}, err
+	}
+
 	id, err := generateID()
 	if err != nil {
 		return models.User{}, err
@@ -508,15 +830,17 @@ func (s *Storage) CreateUser(params CreateUserParams) (models.User, error) {
 		passwordHash = hashed
 	}
 
-	now := time.Now().UTC()
 	user := models.User{
-		ID:           id,
-		DisplayName:  displayName,
-		Email:        normalizedEmail,
-		Roles:        roles,
-		PasswordHash: passwordHash,
-		SelfSignup:   params.SelfSignup,
-		CreatedAt:    now,
+		ID:                id,
+		DisplayName:       displayName,
+		Username:          username,
+		Email:             normalizedEmail,
+		Roles:             roles,
+		PasswordHash:      passwordHash,
+		SelfSignup:        params.SelfSignup,
+		CreatedAt:         now,
+		UsernameChangedAt: usernameChangedAt,
+		UsernameGrace:     usernameChangedAt == nil,
 	}
 
 	s.data.Users[id] = user
@@ -528,7 +852,7 @@ func (s *Storage) CreateUser(params CreateUserParams) (models.User, error) {
 	return user, nil
 }
 
-func (s *Storage) ListUsers() []models.User {
+func (s *Storage) ListUsers() ([]models.User, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -537,9 +861,12 @@ func (s *Storage) ListUsers() []models.User {
 		users = append(users, user)
 	}
 	sort.Slice(users, func(i, j int) bool {
-		return users[i].CreatedAt.Before(users[j].CreatedAt)
+		if !users[i].CreatedAt.Equal(users[j].CreatedAt) {
+			return users[i].CreatedAt.Before(users[j].CreatedAt)
+		}
+		return users[i].ID < users[j].ID
 	})
-	return users
+	return users, nil
 }
 
 func (s *Storage) GetUser(id string) (models.User, bool) {
@@ -566,10 +893,10 @@ func (s *Storage) AuthenticateOAuth(params OAuthLoginParams) (models.User, error
 	provider := strings.ToLower(strings.TrimSpace(params.Provider))
 	subject := strings.TrimSpace(params.Subject)
 	if provider == "" {
-		return models.User{}, errors.New("provider is required")
+		return models.User{}, invalid("provider", "provider is required")
 	}
 	if subject == "" {
-		return models.User{}, errors.New("subject is required")
+		return models.User{}, invalid("subject", "subject is required")
 	}
 
 	normalizedEmail := strings.TrimSpace(strings.ToLower(params.Email))
@@ -593,6 +920,9 @@ func (s *Storage) AuthenticateOAuth(params OAuthLoginParams) (models.User, error
 	key := oauthAccountKey(provider, subject)
 	if account, ok := s.data.OAuthAccounts[key]; ok {
 		if user, ok := s.data.Users[account.UserID]; ok {
+			if user.Deactivated() {
+				return models.User{}, ErrUserDeactivated
+			}
 			return user, nil
 		}
 		delete(s.data.OAuthAccounts, key)
@@ -610,19 +940,28 @@ func (s *Storage) AuthenticateOAuth(params OAuthLoginParams) (models.User, error
 		}
 	}
 
+	if exists && user.Deactivated() {
+		return models.User{}, ErrUserDeactivated
+	}
+
 	now := time.Now().UTC()
 	if !exists {
 		id, err := generateID()
 		if err != nil {
 			return models.User{}, err
 		}
+		username, _ := deriveUsername(displayName, normalizedEmail, func(key string) (bool, error) {
+			return usernameTaken(s.data.Users, key, ""), nil
+		})
 		user = models.User{
-			ID:          id,
-			DisplayName: displayName,
-			Email:       normalizedEmail,
-			Roles:       []string{"viewer"},
-			SelfSignup:  true,
-			CreatedAt:   now,
+			ID:            id,
+			DisplayName:   displayName,
+			Username:      username,
+			Email:         normalizedEmail,
+			Roles:         []string{"viewer"},
+			SelfSignup:    true,
+			CreatedAt:     now,
+			UsernameGrace: true,
 		}
 	} else {
 		if strings.TrimSpace(user.DisplayName) == "" {
@@ -654,10 +993,13 @@ func (s *Storage) AuthenticateOAuth(params OAuthLoginParams) (models.User, error
 }
 
 // UserUpdate represents the fields that can be modified for an existing user.
+// Deactivated set to true deactivates the account, keeping the original
+// deactivation time if it already was; false reactivates it.
 type UserUpdate struct {
 	DisplayName *string
 	Email       *string
 	Roles       *[]string
+	Deactivated *bool
 }
 
 // UpdateUser mutates user metadata while enforcing uniqueness constraints.
@@ -669,13 +1011,13 @@ func (s *Storage) UpdateUser(id string, update UserUpdate) (models.User, error)
 
 	user, ok := updatedData.Users[id]
 	if !ok {
-		return models.User{}, fmt.Errorf("user %s not found", id)
+		return models.User{}, notFound("user", id)
 	}
 
 	if update.DisplayName != nil {
 		name := strings.TrimSpace(*update.DisplayName)
 		if name == "" {
-			return models.User{}, errors.New("displayName cannot be empty")
+			return models.User{}, invalid("displayName", "displayName cannot be empty")
 		}
 		user.DisplayName = name
 	}
@@ -683,14 +1025,14 @@ func (s *Storage) UpdateUser(id string, update UserUpdate) (models.User, error)
 	if update.Email != nil {
 		email := strings.TrimSpace(strings.ToLower(*update.Email))
 		if email == "" {
-			return models.User{}, errors.New("email cannot be empty")
+			return models.User{}, invalid("email", "email cannot be empty")
 		}
 		for existingID, existing := range updatedData.Users {
 			if existingID == user.ID {
 				continue
 			}
 			if existing.Email == email {
-				return models.User{}, fmt.Errorf("email %s already in use", email)
+				return models.User{}, conflict("email", "email %s already in use", email)
 			}
 		}
 		user.Email = email
@@ -700,6 +1042,10 @@ func (s *Storage) UpdateUser(id string, update UserUpdate) (models.User, error)
 		user.Roles = normalizeRoles(*update.Roles)
 	}
 
+	if update.Deactivated != nil {
+		user.DeactivatedAt = applyDeactivation(user.DeactivatedAt, *update.Deactivated, time.Now().UTC())
+	}
+
 	updatedData.Users[id] = user
 	if err := s.persistDataset(updatedData); err != nil {
 		return models.User{}, err
@@ -719,18 +1065,43 @@ func (s *Storage) DeleteUser(id string) error {
 	updatedData := cloneDataset(s.data)
 
 	if _, ok := updatedData.Users[id]; !ok {
-		return fmt.Errorf("user %s not found", id)
+		return notFound("user", id)
 	}
 
 	for _, channel := range updatedData.Channels {
 		if channel.OwnerID == id {
-			return fmt.Errorf("user %s owns channel %s; transfer or delete the channel first", id, channel.ID)
+			return precondition(fmt.Sprintf("user %s owns channel %s; transfer or delete the channel first", id, channel.ID))
 		}
 	}
 
 	delete(updatedData.Users, id)
 	delete(updatedData.Profiles, id)
 	delete(updatedData.Follows, id)
+	for channelID, editors := range updatedData.ChannelEditors {
+		if _, exists := editors[id]; exists {
+			delete(editors, id)
+			if len(editors) == 0 {
+				delete(updatedData.ChannelEditors, channelID)
+			}
+		}
+	}
+	for channelID, moderators := range updatedData.ChannelModerators {
+		if _, exists := moderators[id]; exists {
+			delete(moderators, id)
+			if len(moderators) == 0 {
+				delete(updatedData.ChannelModerators, channelID)
+			}
+		}
+	}
+	for tokenID, token := range updatedData.APITokens {
+		if token.UserID == id {
+			delete(updatedData.APITokens, tokenID)
+		}
+	}
+	delete(updatedData.NotificationPreferences, id)
+	delete(updatedData.PlaybackPreferences, id)
+	delete(updatedData.BadgeGrants, id)
+	delete(updatedData.ScheduleFeedTokens, id)
 
 	now := time.Now().UTC()
 	for profileID, profile := range updatedData.Profiles {
@@ -753,6 +1124,52 @@ func (s *Storage) DeleteUser(id string) error {
 			delete(updatedData.ChatMessages, messageID)
 		}
 	}
+	for appealID, appeal := range updatedData.ChatAppeals {
+		switch {
+		case appeal.UserID == id:
+			delete(updatedData.ChatAppeals, appealID)
+		case appeal.ResolverID == id:
+			appeal.ResolverID = ""
+			updatedData.ChatAppeals[appealID] = appeal
+		}
+	}
+	for actionID, action := range updatedData.ModerationActions {
+		switch {
+		case action.TargetID == id:
+			delete(updatedData.ModerationActions, actionID)
+		case action.ActorID == id:
+			action.ActorID = ""
+			updatedData.ModerationActions[actionID] = action
+		}
+	}
+	for clipID, clip := range updatedData.ClipExports {
+		if clip.CreatedBy == id {
+			clip.CreatedBy = ""
+			updatedData.ClipExports[clipID] = clip
+		}
+	}
+	for markerID, marker := range updatedData.StreamMarkers {
+		if marker.CreatedBy == id {
+			marker.CreatedBy = ""
+			updatedData.StreamMarkers[markerID] = marker
+		}
+	}
+	for notificationID, notification := range updatedData.Notifications {
+		if notification.UserID == id {
+			delete(updatedData.Notifications, notificationID)
+		}
+	}
+	for channelID, pin := range updatedData.ChatPins {
+		if pin.AuthorID == id || pin.PinnedBy == id {
+			if pin.AuthorID == id {
+				pin.AuthorID = ""
+			}
+			if pin.PinnedBy == id {
+				pin.PinnedBy = ""
+			}
+			updatedData.ChatPins[channelID] = pin
+		}
+	}
 
 	if err := s.persistDataset(updatedData); err != nil {
 		return err
@@ -776,13 +1193,17 @@ type ProfileUpdate struct {
 }
 
 func (s *Storage) UpsertProfile(userID string, update ProfileUpdate) (models.Profile, error) {
+	if err := normalizeProfileImageUpdate(&update, s.objectStorage); err != nil {
+		return models.Profile{}, err
+	}
+
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	updatedData := cloneDataset(s.data)
 
 	if _, ok := updatedData.Users[userID]; !ok {
-		return models.Profile{}, fmt.Errorf("user %s not found", userID)
+		return models.Profile{}, notFound("user", userID)
 	}
 
 	profile, exists := updatedData.Profiles[userID]
@@ -820,10 +1241,10 @@ func (s *Storage) UpsertProfile(userID string, update ProfileUpdate) (models.Pro
 		} else {
 			channel, ok := updatedData.Channels[trimmed]
 			if !ok {
-				return models.Profile{}, fmt.Errorf("featured channel %s not found", trimmed)
+				return models.Profile{}, notFound("featured channel", trimmed)
 			}
 			if channel.OwnerID != userID {
-				return models.Profile{}, errors.New("featured channel must belong to profile owner")
+				return models.Profile{}, invalid("featuredChannelId", "featured channel must belong to profile owner")
 			}
 			id := channel.ID
 			profile.FeaturedChannelID = &id
@@ -831,23 +1252,23 @@ func (s *Storage) UpsertProfile(userID string, update ProfileUpdate) (models.Pro
 	}
 	if update.TopFriends != nil {
 		if len(*update.TopFriends) > 8 {
-			return models.Profile{}, errors.New("top friends cannot exceed eight entries")
+			return models.Profile{}, invalid("topFriends", "top friends cannot exceed eight entries")
 		}
 		seen := make(map[string]struct{})
 		ordered := make([]string, 0, len(*update.TopFriends))
 		for _, friendID := range *update.TopFriends {
 			trimmed := strings.TrimSpace(friendID)
 			if trimmed == "" {
-				return models.Profile{}, errors.New("top friends must reference valid users")
+				return models.Profile{}, invalid("topFriends", "top friends must reference valid users")
 			}
 			if trimmed == userID {
-				return models.Profile{}, errors.New("cannot add profile owner as a top friend")
+				return models.Profile{}, invalid("topFriends", "cannot add profile owner as a top friend")
 			}
 			if _, friendExists := updatedData.Users[trimmed]; !friendExists {
-				return models.Profile{}, fmt.Errorf("top friend %s not found", trimmed)
+				return models.Profile{}, notFound("top friend", trimmed)
 			}
 			if _, duplicate := seen[trimmed]; duplicate {
-				return models.Profile{}, errors.New("duplicate user in top friends list")
+				return models.Profile{}, invalid("topFriends", "duplicate user in top friends list")
 			}
 			seen[trimmed] = struct{}{}
 			ordered = append(ordered, trimmed)
@@ -855,13 +1276,9 @@ func (s *Storage) UpsertProfile(userID string, update ProfileUpdate) (models.Pro
 		profile.TopFriends = ordered
 	}
 	if update.DonationAddresses != nil {
-		addresses := make([]models.CryptoAddress, 0, len(*update.DonationAddresses))
-		for _, addr := range *update.DonationAddresses {
-			normalized, err := NormalizeDonationAddress(addr)
-			if err != nil {
-				return models.Profile{}, err
-			}
-			addresses = append(addresses, normalized)
+		addresses, err := NormalizeDonationAddresses(profile.DonationAddresses, *update.DonationAddresses)
+		if err != nil {
+			return models.Profile{}, err
 		}
 		profile.DonationAddresses = addresses
 	}
@@ -871,16 +1288,41 @@ func (s *Storage) UpsertProfile(userID string, update ProfileUpdate) (models.Pro
 		profile.CreatedAt = now
 	}
 
+	previous := updatedData.Profiles[userID]
 	updatedData.Profiles[userID] = profile
 	if err := s.persistDataset(updatedData); err != nil {
 		return models.Profile{}, err
 	}
 
 	s.data = updatedData
+	deleteReplacedProfileImages(s.objectClient, s.objectStorage, previous, profile)
 
 	return profile, nil
 }
 
+// VerifyDonationAddress records that the owner of userID's profile proved
+// control of its donation address of currency at verifiedAt.
+func (s *Storage) VerifyDonationAddress(userID string, currency models.CryptoCurrency, address string, verifiedAt time.Time) (models.Profile, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	profile, ok := s.data.Profiles[userID]
+	if !ok {
+		return models.Profile{}, ErrDonationAddressNotFound
+	}
+	updatedData := cloneDataset(s.data)
+	profile.DonationAddresses = append([]models.CryptoAddress(nil), profile.DonationAddresses...)
+	if err := markDonationAddressVerified(profile.DonationAddresses, currency, address, verifiedAt); err != nil {
+		return models.Profile{}, err
+	}
+	updatedData.Profiles[userID] = profile
+	if err := s.persistDataset(updatedData); err != nil {
+		return models.Profile{}, err
+	}
+	s.data = updatedData
+	return profile, nil
+}
+
 func (s *Storage) GetProfile(userID string) (models.Profile, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
@@ -915,7 +1357,7 @@ func (s *Storage) GetProfile(userID string) (models.Profile, bool) {
 	return profile, true
 }
 
-func (s *Storage) ListProfiles() []models.Profile {
+func (s *Storage) ListProfiles() ([]models.Profile, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
@@ -933,18 +1375,98 @@ func (s *Storage) ListProfiles() []models.Profile {
 		profiles = append(profiles, profile)
 	}
 	sort.Slice(profiles, func(i, j int) bool {
-		return profiles[i].CreatedAt.Before(profiles[j].CreatedAt)
+		if !profiles[i].CreatedAt.Equal(profiles[j].CreatedAt) {
+			return profiles[i].CreatedAt.Before(profiles[j].CreatedAt)
+		}
+		return profiles[i].UserID < profiles[j].UserID
 	})
-	return profiles
+	return profiles, nil
 }
 
 // Channel operations
 
 type ChannelUpdate struct {
-	Title     *string
-	Category  *string
-	Tags      *[]string
-	LiveState *string
+	Title               *string
+	Category            *string
+	Tags                *[]string
+	LiveState           *string
+	PlaybackRestriction *string
+	PlaybackPreviews    *bool
+	MatureContent       *bool
+	RecordingPolicy     *string
+	// Language replaces the channel's BCP-47 language tag; empty clears it.
+	// ContentWarnings replaces the channel's content warnings; an empty list
+	// clears them.
+	Language        *string
+	ContentWarnings *[]string
+	// TranscodeLimits replaces the channel's override of the platform
+	// transcode caps. A value with every field zero clears the override.
+	TranscodeLimits *models.TranscodeLimits
+	// ChatMaxCapsPercent is zero to turn the chat caps rule off, or the
+	// percentage of capitals above which messages are lowercased.
+	ChatMaxCapsPercent  *int
+	ChatRestrictLinks   *bool
+	ChatSubscribersOnly *bool
+	// ChatWelcomeMessage replaces the message clients show viewers joining
+	// the chat; empty clears it.
+	ChatWelcomeMessage *string
+}
+
+// normalizePlaybackRestriction validates a channel playback restriction,
+// mapping "none" and blank values to the unrestricted empty string.
+func normalizePlaybackRestriction(value string) (string, error) {
+	restriction := strings.ToLower(strings.TrimSpace(value))
+	switch restriction {
+	case "", "none":
+		return "", nil
+	case models.PlaybackRestrictionFollowers, models.PlaybackRestrictionSubscribers:
+		return restriction, nil
+	default:
+		return "", invalid("playbackRestriction", "invalid playbackRestriction %s", restriction)
+	}
+}
+
+// normalizeRecordingPolicy validates a channel recording policy, mapping
+// blank values to manual.
+func normalizeRecordingPolicy(value string) (string, error) {
+	policy := strings.ToLower(strings.TrimSpace(value))
+	switch policy {
+	case "":
+		return models.RecordingPolicyManual, nil
+	case models.RecordingPolicyManual, models.RecordingPolicyAutoPublish, models.RecordingPolicyDiscard:
+		return policy, nil
+	default:
+		return "", invalid("recordingPolicy", "invalid recordingPolicy %s", policy)
+	}
+}
+
+// normalizeTranscodeLimits validates a channel transcode cap override. An
+// all-zero override normalizes to nil so the channel falls back to the
+// platform defaults.
+func normalizeTranscodeLimits(limits models.TranscodeLimits) (*models.TranscodeLimits, error) {
+	if limits.MaxHeight < 0 || limits.MaxBitrate < 0 || limits.MaxRenditions < 0 {
+		return nil, invalid("transcodeLimits", "transcodeLimits values cannot be negative")
+	}
+	if limits.IsZero() {
+		return nil, nil
+	}
+	return &limits, nil
+}
+
+// isPermanentBootError reports whether a boot failed in a way retrying
+// cannot change: the ladder breaks the transcode caps, or the transcoder is
+// out of disk.
+func isPermanentBootError(err error) bool {
+	var limitErr *ingest.TranscodeLimitError
+	return errors.As(err, &limitErr) || errors.Is(err, ingest.ErrTranscoderOutOfStorage)
+}
+
+func cloneTranscodeLimits(limits *models.TranscodeLimits) *models.TranscodeLimits {
+	if limits == nil {
+		return nil
+	}
+	cloned := *limits
+	return &cloned
 }
 
 func (s *Storage) CreateChannel(ownerID, title, category string, tags []string) (models.Channel, error) {
@@ -952,32 +1474,45 @@ func (s *Storage) CreateChannel(ownerID, title, category string, tags []string)
 	defer s.mu.Unlock()
 
 	if _, ok := s.data.Users[ownerID]; !ok {
-		return models.Channel{}, fmt.Errorf("owner %s not found", ownerID)
+		return models.Channel{}, notFound("owner", ownerID)
 	}
 	if title = strings.TrimSpace(title); title == "" {
-		return models.Channel{}, errors.New("title is required")
+		return models.Channel{}, invalid("title", "title is required")
+	}
+	category, err := normalizeChannelCategory(category)
+	if err != nil {
+		return models.Channel{}, err
 	}
 
 	id, err := generateID()
 	if err != nil {
 		return models.Channel{}, err
 	}
-	streamKey, err := generateStreamKey()
+	streamKey, streamKeyHash, streamKeyHint, err := newChannelStreamKey()
+	if err != nil {
+		return models.Channel{}, err
+	}
+	slug, err := deriveChannelSlug(title, func(candidate string) (bool, error) {
+		return channelSlugTaken(s.data.Channels, s.data.ChannelSlugRedirects, candidate, ""), nil
+	})
 	if err != nil {
 		return models.Channel{}, err
 	}
 
 	now := time.Now().UTC()
 	channel := models.Channel{
-		ID:        id,
-		OwnerID:   ownerID,
-		StreamKey: streamKey,
-		Title:     title,
-		Category:  strings.TrimSpace(category),
-		Tags:      normalizeTags(tags),
-		LiveState: "offline",
-		CreatedAt: now,
-		UpdatedAt: now,
+		ID:              id,
+		OwnerID:         ownerID,
+		Slug:            slug,
+		StreamKeyHash:   streamKeyHash,
+		StreamKeyHint:   streamKeyHint,
+		Title:           title,
+		Category:        category,
+		Tags:            normalizeTags(tags),
+		LiveState:       "offline",
+		CreatedAt:       now,
+		UpdatedAt:       now,
+		RecordingPolicy: models.RecordingPolicyManual,
 	}
 
 	s.data.Channels[id] = channel
@@ -986,6 +1521,7 @@ func (s *Storage) CreateChannel(ownerID, title, category string, tags []string)
 		return models.Channel{}, err
 	}
 
+	channel.StreamKey = streamKey
 	return channel, nil
 }
 
@@ -1018,18 +1554,22 @@ func (s *Storage) UpdateChannel(id string, update ChannelUpdate) (models.Channel
 
 	channel, ok := updatedData.Channels[id]
 	if !ok {
-		return models.Channel{}, fmt.Errorf("channel %s not found", id)
+		return models.Channel{}, notFound("channel", id)
 	}
 
 	if update.Title != nil {
 		if title := strings.TrimSpace(*update.Title); title != "" {
 			channel.Title = title
 		} else {
-			return models.Channel{}, errors.New("title cannot be empty")
+			return models.Channel{}, invalid("title", "title cannot be empty")
 		}
 	}
 	if update.Category != nil {
-		channel.Category = strings.TrimSpace(*update.Category)
+		category, err := normalizeChannelCategory(*update.Category)
+		if err != nil {
+			return models.Channel{}, err
+		}
+		channel.Category = category
 	}
 	if update.Tags != nil {
 		channel.Tags = normalizeTags(*update.Tags)
@@ -1037,9 +1577,70 @@ func (s *Storage) UpdateChannel(id string, update ChannelUpdate) (models.Channel
 	if update.LiveState != nil {
 		state := strings.ToLower(strings.TrimSpace(*update.LiveState))
 		if state != "offline" && state != "live" && state != "starting" && state != "ended" {
-			return models.Channel{}, fmt.Errorf("invalid liveState %s", state)
+			return models.Channel{}, invalid("liveState", "invalid liveState %s", state)
+		}
+		setLiveState(&channel, state, time.Now().UTC())
+	}
+	if update.PlaybackRestriction != nil {
+		restriction, err := normalizePlaybackRestriction(*update.PlaybackRestriction)
+		if err != nil {
+			return models.Channel{}, err
+		}
+		channel.PlaybackRestriction = restriction
+	}
+	if update.PlaybackPreviews != nil {
+		channel.PlaybackPreviews = *update.PlaybackPreviews
+	}
+	if update.MatureContent != nil {
+		channel.MatureContent = *update.MatureContent
+	}
+	if update.RecordingPolicy != nil {
+		policy, err := normalizeRecordingPolicy(*update.RecordingPolicy)
+		if err != nil {
+			return models.Channel{}, err
+		}
+		channel.RecordingPolicy = policy
+	}
+	if update.Language != nil {
+		language, err := normalizeChannelLanguage(*update.Language)
+		if err != nil {
+			return models.Channel{}, err
+		}
+		channel.Language = language
+	}
+	if update.ContentWarnings != nil {
+		warnings, err := normalizeContentWarnings(*update.ContentWarnings)
+		if err != nil {
+			return models.Channel{}, err
+		}
+		channel.ContentWarnings = warnings
+	}
+	if update.TranscodeLimits != nil {
+		limits, err := normalizeTranscodeLimits(*update.TranscodeLimits)
+		if err != nil {
+			return models.Channel{}, err
 		}
-		channel.LiveState = state
+		channel.TranscodeLimits = limits
+	}
+	if update.ChatMaxCapsPercent != nil {
+		percent, err := chat.NormalizeMaxCapsPercent(*update.ChatMaxCapsPercent)
+		if err != nil {
+			return models.Channel{}, err
+		}
+		channel.ChatMaxCapsPercent = percent
+	}
+	if update.ChatRestrictLinks != nil {
+		channel.ChatRestrictLinks = *update.ChatRestrictLinks
+	}
+	if update.ChatSubscribersOnly != nil {
+		channel.ChatSubscribersOnly = *update.ChatSubscribersOnly
+	}
+	if update.ChatWelcomeMessage != nil {
+		welcome, err := chat.NormalizeWelcomeMessage(*update.ChatWelcomeMessage)
+		if err != nil {
+			return models.Channel{}, err
+		}
+		channel.ChatWelcomeMessage = welcome
 	}
 
 	channel.UpdatedAt = time.Now().UTC()
@@ -1061,15 +1662,16 @@ func (s *Storage) RotateChannelStreamKey(id string) (models.Channel, error) {
 
 	channel, ok := updatedData.Channels[id]
 	if !ok {
-		return models.Channel{}, fmt.Errorf("channel %s not found", id)
+		return models.Channel{}, notFound("channel", id)
 	}
 
-	streamKey, err := generateStreamKey()
+	streamKey, streamKeyHash, streamKeyHint, err := newChannelStreamKey()
 	if err != nil {
 		return models.Channel{}, err
 	}
 
-	channel.StreamKey = streamKey
+	channel.StreamKeyHash = streamKeyHash
+	channel.StreamKeyHint = streamKeyHint
 	channel.UpdatedAt = time.Now().UTC()
 	updatedData.Channels[id] = channel
 
@@ -1079,6 +1681,7 @@ func (s *Storage) RotateChannelStreamKey(id string) (models.Channel, error) {
 
 	s.data = updatedData
 
+	channel.StreamKey = streamKey
 	return channel, nil
 }
 
@@ -1089,18 +1692,19 @@ func (s *Storage) GetChannel(id string) (models.Channel, bool) {
 	return channel, ok
 }
 
-// GetChannelByStreamKey looks up a channel by its stream key.
-func (s *Storage) GetChannelByStreamKey(streamKey string) (models.Channel, bool) {
+// FindChannelByStreamKeyHash looks up a channel by the digest of its stream
+// key, as produced by HashStreamKey.
+func (s *Storage) FindChannelByStreamKeyHash(hash string) (models.Channel, bool) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	key := strings.TrimSpace(streamKey)
-	if key == "" {
+	hash = strings.TrimSpace(hash)
+	if hash == "" {
 		return models.Channel{}, false
 	}
 
 	for _, channel := range s.data.Channels {
-		if channel.StreamKey == key {
+		if subtle.ConstantTimeCompare([]byte(channel.StreamKeyHash), []byte(hash)) == 1 {
 			return channel, true
 		}
 	}
@@ -1108,14 +1712,28 @@ func (s *Storage) GetChannelByStreamKey(streamKey string) (models.Channel, bool)
 	return models.Channel{}, false
 }
 
-func (s *Storage) ListChannels(ownerID, query string) []models.Channel {
+func (s *Storage) ListChannels(ownerID, query string) ([]models.Channel, error) {
+	return s.ListChannelsMatching(ChannelFilter{OwnerID: ownerID, Query: query})
+}
+
+// ListChannelsMatching lists the channels passing filter, live channels
+// first and then oldest first.
+func (s *Storage) ListChannelsMatching(filter ChannelFilter) ([]models.Channel, error) {
+	filter, err := filter.normalized()
+	if err != nil {
+		return nil, err
+	}
+
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
-	normalizedQuery := strings.ToLower(strings.TrimSpace(query))
+	normalizedQuery := strings.ToLower(filter.Query)
 	channels := make([]models.Channel, 0, len(s.data.Channels))
 	for _, channel := range s.data.Channels {
-		if ownerID != "" && channel.OwnerID != ownerID {
+		if filter.OwnerID != "" && channel.OwnerID != filter.OwnerID {
+			continue
+		}
+		if !channelMatchesMetadata(channel, filter) {
 			continue
 		}
 		if normalizedQuery != "" {
@@ -1127,12 +1745,16 @@ func (s *Storage) ListChannels(ownerID, query string) []models.Channel {
 		channels = append(channels, channel)
 	}
 	sort.Slice(channels, func(i, j int) bool {
-		if channels[i].LiveState == channels[j].LiveState {
+		iLive, jLive := channels[i].LiveState == "live", channels[j].LiveState == "live"
+		if iLive != jLive {
+			return iLive
+		}
+		if !channels[i].CreatedAt.Equal(channels[j].CreatedAt) {
 			return channels[i].CreatedAt.Before(channels[j].CreatedAt)
 		}
-		return channels[i].LiveState == "live"
+		return channels[i].ID < channels[j].ID
 	})
-	return channels
+	return channels, nil
 }
 
 func channelMatchesQuery(channel models.Channel, owner models.User, normalizedQuery string) bool {
@@ -1161,10 +1783,10 @@ func (s *Storage) FollowChannel(userID, channelID string) error {
 	updatedData := cloneDataset(s.data)
 
 	if _, ok := updatedData.Users[userID]; !ok {
-		return fmt.Errorf("user %s not found", userID)
+		return notFound("user", userID)
 	}
 	if _, ok := updatedData.Channels[channelID]; !ok {
-		return fmt.Errorf("channel %s not found", channelID)
+		return notFound("channel", channelID)
 	}
 
 	if updatedData.Follows == nil {
@@ -1196,10 +1818,10 @@ func (s *Storage) UnfollowChannel(userID, channelID string) error {
 	updatedData := cloneDataset(s.data)
 
 	if _, ok := updatedData.Users[userID]; !ok {
-		return fmt.Errorf("user %s not found", userID)
+		return notFound("user", userID)
 	}
 	if _, ok := updatedData.Channels[channelID]; !ok {
-		return fmt.Errorf("channel %s not found", channelID)
+		return notFound("channel", channelID)
 	}
 
 	if follows, ok := updatedData.Follows[userID]; ok {
@@ -1253,13 +1875,13 @@ func (s *Storage) CountFollowers(channelID string) int {
 }
 
 // ListFollowedChannelIDs returns the identifiers of channels the user follows ordered by recency.
-func (s *Storage) ListFollowedChannelIDs(userID string) []string {
+func (s *Storage) ListFollowedChannelIDs(userID string) ([]string, error) {
 	s.mu.RLock()
 	defer s.mu.RUnlock()
 
 	follows, ok := s.data.Follows[userID]
 	if !ok || len(follows) == 0 {
-		return nil
+		return []string{}, nil
 	}
 
 	type pair struct {
@@ -1273,14 +1895,50 @@ func (s *Storage) ListFollowedChannelIDs(userID string) []string {
 	}
 
 	sort.Slice(pairs, func(i, j int) bool {
-		return pairs[i].when.After(pairs[j].when)
+		if !pairs[i].when.Equal(pairs[j].when) {
+			return pairs[i].when.After(pairs[j].when)
+		}
+		return pairs[i].id < pairs[j].id
 	})
 
 	ids := make([]string, 0, len(pairs))
 	for _, p := range pairs {
 		ids = append(ids, p.id)
 	}
-	return ids
+	return ids, nil
+}
+
+// ListChannelFollowers pages the channel's followers newest first, skipping
+// follows left behind by users that no longer exist.
+func (s *Storage) ListChannelFollowers(channelID string, opts FollowerListOptions) (FollowerPage, error) {
+	s.mu.RLock()
+	followers := make([]ChannelFollower, 0)
+	for userID, follows := range s.data.Follows {
+		followedAt, ok := follows[channelID]
+		if !ok {
+			continue
+		}
+		user, ok := s.data.Users[userID]
+		if !ok {
+			continue
+		}
+		followers = append(followers, ChannelFollower{
+			UserID:      user.ID,
+			DisplayName: user.DisplayName,
+			AvatarURL:   s.data.Profiles[userID].AvatarURL,
+			FollowedAt:  followedAt,
+		})
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(followers, func(i, j int) bool {
+		if followers[i].FollowedAt.Equal(followers[j].FollowedAt) {
+			return followers[i].UserID > followers[j].UserID
+		}
+		return followers[i].FollowedAt.After(followers[j].FollowedAt)
+	})
+	start, end := pageBounds(len(followers), UserListOptions{Limit: opts.Limit, Offset: opts.Offset})
+	return FollowerPage{Followers: followers[start:end], Total: len(followers)}, nil
 }
 
 // DeleteChannel removes a channel and its associated sessions and chat transcripts.
@@ -1292,10 +1950,10 @@ func (s *Storage) DeleteChannel(id string) error {
 
 	channel, ok := updatedData.Channels[id]
 	if !ok {
-		return fmt.Errorf("channel %s not found", id)
+		return notFound("channel", id)
 	}
 	if channel.CurrentSessionID != nil {
-		return errors.New("cannot delete a channel with an active stream")
+		return precondition("cannot delete a channel with an active stream")
 	}
 
 	delete(updatedData.Channels, id)
@@ -1305,11 +1963,56 @@ func (s *Storage) DeleteChannel(id string) error {
 			delete(updatedData.StreamSessions, sessionID)
 		}
 	}
+	for markerID, marker := range updatedData.StreamMarkers {
+		if marker.ChannelID == id {
+			delete(updatedData.StreamMarkers, markerID)
+		}
+	}
+	for targetID, target := range updatedData.RestreamTargets {
+		if target.ChannelID == id {
+			delete(updatedData.RestreamTargets, targetID)
+		}
+	}
+	for entryID, entry := range updatedData.ScheduleEntries {
+		if entry.ChannelID == id {
+			delete(updatedData.ScheduleEntries, entryID)
+		}
+	}
+	for inviteID, invite := range updatedData.CoStreamInvites {
+		if invite.HostChannelID == id || invite.GuestChannelID == id {
+			delete(updatedData.CoStreamInvites, inviteID)
+		}
+	}
+	for slug, redirect := range updatedData.ChannelSlugRedirects {
+		if redirect.ChannelID == id {
+			delete(updatedData.ChannelSlugRedirects, slug)
+		}
+	}
 	for messageID, message := range updatedData.ChatMessages {
 		if message.ChannelID == id {
 			delete(updatedData.ChatMessages, messageID)
 		}
 	}
+	for appealID, appeal := range updatedData.ChatAppeals {
+		if appeal.ChannelID == id {
+			delete(updatedData.ChatAppeals, appealID)
+		}
+	}
+	for actionID, action := range updatedData.ModerationActions {
+		if action.ChannelID == id {
+			delete(updatedData.ModerationActions, actionID)
+		}
+	}
+	for eventID, event := range updatedData.SecurityEvents {
+		if event.ChannelID == id {
+			delete(updatedData.SecurityEvents, eventID)
+		}
+	}
+	delete(updatedData.ChannelEditors, id)
+	delete(updatedData.ChannelModerators, id)
+	delete(updatedData.ChannelStorage, id)
+	delete(updatedData.ChatPins, id)
+	delete(updatedData.ChatSequences, id)
 	for userID, follows := range updatedData.Follows {
 		if follows == nil {
 			continue
@@ -1343,15 +2046,27 @@ func (s *Storage) DeleteChannel(id string) error {
 // Streaming operations
 
 func (s *Storage) StartStream(channelID string, renditions []string) (models.StreamSession, error) {
+	return s.StartStreamContext(context.Background(), channelID, renditions)
+}
+
+// StartStreamContext reserves a session for the channel and boots ingest
+// for it within ctx. When ctx ends first the channel is left starting and
+// ErrStreamStartTimedOut is returned; RecoverStream later tears down
+// whatever the cut-short boot left behind.
+func (s *Storage) StartStreamContext(ctx context.Context, channelID string, renditions []string) (models.StreamSession, error) {
 	s.mu.Lock()
 	channel, ok := s.data.Channels[channelID]
 	if !ok {
 		s.mu.Unlock()
-		return models.StreamSession{}, fmt.Errorf("channel %s not found", channelID)
+		return models.StreamSession{}, notFound("channel", channelID)
 	}
-	if channel.CurrentSessionID != nil {
+	if err := checkStartAllowed(channel, time.Now().UTC(), s.startingTimeout); err != nil {
 		s.mu.Unlock()
-		return models.StreamSession{}, errors.New("channel already live")
+		return models.StreamSession{}, err
+	}
+	if err := s.checkStreamLimitLocked(channel); err != nil {
+		s.mu.Unlock()
+		return models.StreamSession{}, err
 	}
 
 	sessionID, err := generateID()
@@ -1361,54 +2076,43 @@ func (s *Storage) StartStream(channelID string, renditions []string) (models.Str
 	}
 
 	channel.CurrentSessionID = &sessionID
-	channel.LiveState = "starting"
+	setLiveState(&channel, "starting", time.Now().UTC())
 	s.data.Channels[channelID] = channel
+	restreams := bootRestreams(s.secrets, s.restreamTargetsLocked(channelID))
 	s.mu.Unlock()
 
-	controller := s.ingestController
-	if controller == nil {
+	// abandonStart returns the channel to offline unless a recovery has
+	// already released it, possibly to a newer start.
+	abandonStart := func() {
 		s.mu.Lock()
-		if updated, exists := s.data.Channels[channelID]; exists {
+		if updated, exists := s.data.Channels[channelID]; exists && updated.CurrentSessionID != nil && *updated.CurrentSessionID == sessionID {
 			updated.CurrentSessionID = nil
-			updated.LiveState = "offline"
+			setLiveState(&updated, "offline", time.Now().UTC())
 			s.data.Channels[channelID] = updated
 		}
 		s.mu.Unlock()
-		return models.StreamSession{}, ErrIngestControllerUnavailable
 	}
 
-	attempts := s.ingestMaxAttempts
-	if attempts <= 0 {
-		attempts = 1
+	controller := s.ingestController
+	if controller == nil {
+		abandonStart()
+		return models.StreamSession{}, ErrIngestControllerUnavailable
 	}
+
 	timeout := normalizeIngestTimeout(s.ingestTimeout)
-	var boot ingest.BootResult
-	var bootErr error
-	for attempt := 0; attempt < attempts; attempt++ {
-		ctx, cancel := context.WithTimeout(context.Background(), timeout)
-		boot, bootErr = controller.BootStream(ctx, ingest.BootParams{
-			ChannelID:  channelID,
-			SessionID:  sessionID,
-			StreamKey:  channel.StreamKey,
-			Renditions: append([]string{}, renditions...),
-		})
-		cancel()
-		if bootErr == nil {
-			break
-		}
-		if attempt < attempts-1 && s.ingestRetryInterval > 0 {
-			time.Sleep(s.ingestRetryInterval)
-		}
-	}
-	if bootErr != nil {
-		s.mu.Lock()
-		if updated, exists := s.data.Channels[channelID]; exists {
-			updated.CurrentSessionID = nil
-			updated.LiveState = "offline"
-			s.data.Channels[channelID] = updated
+	boot, err := bootIngest(ctx, controller, ingest.BootParams{
+		ChannelID:       channelID,
+		SessionID:       sessionID,
+		StreamKey:       channel.StreamKeyHash,
+		Renditions:      append([]string{}, renditions...),
+		TranscodeLimits: cloneTranscodeLimits(channel.TranscodeLimits),
+		Restreams:       restreams,
+	}, s.ingestMaxAttempts, timeout, s.ingestRetryInterval)
+	if err != nil {
+		if !errors.Is(err, ErrStreamStartTimedOut) {
+			abandonStart()
 		}
-		s.mu.Unlock()
-		return models.StreamSession{}, fmt.Errorf("boot ingest: %w", bootErr)
+		return models.StreamSession{}, err
 	}
 
 	now := time.Now().UTC()
@@ -1421,7 +2125,10 @@ func (s *Storage) StartStream(channelID string, renditions []string) (models.Str
 		OriginURL:      boot.OriginURL,
 		PlaybackURL:    boot.PlaybackURL,
 		IngestJobIDs:   append([]string{}, boot.JobIDs...),
+		PreviewURL:     boot.PreviewURL,
+		Settings:       newStreamSettings(renditions, boot, channel.Language),
 	}
+	session.PlaybackEndpoints = clonePlaybackEndpoints(boot.PlaybackEndpoints)
 	ingestEndpoints := make([]string, 0, 2)
 	if boot.PrimaryIngest != "" {
 		ingestEndpoints = append(ingestEndpoints, boot.PrimaryIngest)
@@ -1445,22 +2152,31 @@ func (s *Storage) StartStream(channelID string, renditions []string) (models.Str
 	}
 
 	s.mu.Lock()
+	channel, ok = s.data.Channels[channelID]
+	if !ok || channel.CurrentSessionID == nil || *channel.CurrentSessionID != sessionID {
+		// RecoverStream reset the channel while ingest was booting.
+		s.mu.Unlock()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		_ = controller.ShutdownStream(shutdownCtx, channelID, sessionID, append([]string{}, session.IngestJobIDs...))
+		cancel()
+		return models.StreamSession{}, ErrStreamStartAborted
+	}
 	s.data.StreamSessions[sessionID] = session
-	channel = s.data.Channels[channelID]
-	channel.CurrentSessionID = &sessionID
-	channel.LiveState = "live"
+	setLiveState(&channel, "live", now)
 	channel.UpdatedAt = now
 	s.data.Channels[channelID] = channel
 
 	if err := s.persist(); err != nil {
 		delete(s.data.StreamSessions, sessionID)
 		channel.CurrentSessionID = nil
-		channel.LiveState = "offline"
+		setLiveState(&channel, "offline", now)
 		s.data.Channels[channelID] = channel
 		jobIDs := append([]string{}, session.IngestJobIDs...)
 		s.mu.Unlock()
-		ctx, cancel := context.WithTimeout(context.Background(), timeout)
-		_ = controller.ShutdownStream(ctx, channelID, sessionID, jobIDs)
+		// The session is being discarded, so its teardown runs detached
+		// from the caller's context.
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), timeout)
+		_ = controller.ShutdownStream(shutdownCtx, channelID, sessionID, jobIDs)
 		cancel()
 		return models.StreamSession{}, err
 	}
@@ -1470,15 +2186,22 @@ func (s *Storage) StartStream(channelID string, renditions []string) (models.Str
 }
 
 func (s *Storage) StopStream(channelID string, peakConcurrent int) (models.StreamSession, error) {
+	return s.StopStreamContext(context.Background(), channelID, peakConcurrent)
+}
+
+// StopStreamContext tears the channel's ingest down within ctx and ends its
+// session. When ctx ends first the channel stays live and
+// ErrStreamStopTimedOut is returned.
+func (s *Storage) StopStreamContext(ctx context.Context, channelID string, peakConcurrent int) (models.StreamSession, error) {
 	s.mu.Lock()
 	channel, ok := s.data.Channels[channelID]
 	if !ok {
 		s.mu.Unlock()
-		return models.StreamSession{}, fmt.Errorf("channel %s not found", channelID)
+		return models.StreamSession{}, notFound("channel", channelID)
 	}
 	if channel.CurrentSessionID == nil {
 		s.mu.Unlock()
-		return models.StreamSession{}, errors.New("channel is not live")
+		return models.StreamSession{}, ErrChannelNotLive
 	}
 
 	sessionID := *channel.CurrentSessionID
@@ -1498,47 +2221,76 @@ func (s *Storage) StopStream(channelID string, peakConcurrent int) (models.Strea
 		return models.StreamSession{}, ErrIngestControllerUnavailable
 	}
 
-	timeout := normalizeIngestTimeout(s.ingestTimeout)
-	ctx, cancel := context.WithTimeout(context.Background(), timeout)
-	defer cancel()
-	if err := controller.ShutdownStream(ctx, channelID, sessionID, jobIDs); err != nil {
-		return models.StreamSession{}, fmt.Errorf("shutdown ingest: %w", err)
+	// Discarded streams get no recording, so there is no thumbnail to grab.
+	discard := channel.RecordingPolicy == models.RecordingPolicyDiscard
+	var frame ingest.Frame
+	if !discard {
+		frame, _ = captureSessionFrame(ctx, controller, s.ingestTimeout, jobIDs)
+	}
+	outputBytes, err := shutdownIngest(ctx, controller, channelID, sessionID, jobIDs, normalizeIngestTimeout(s.ingestTimeout))
+	if err != nil {
+		return models.StreamSession{}, err
 	}
 
 	now := time.Now().UTC()
-	session.EndedAt = &now
-	if peakConcurrent > session.PeakConcurrent {
-		session.PeakConcurrent = peakConcurrent
-	}
 
 	s.mu.Lock()
 	channel, ok = s.data.Channels[channelID]
 	if !ok {
 		s.mu.Unlock()
-		return models.StreamSession{}, fmt.Errorf("channel %s not found", channelID)
+		return models.StreamSession{}, notFound("channel", channelID)
+	}
+	// Viewer updates may have raised the peak while ingest shut down.
+	if latest, ok := s.data.StreamSessions[sessionID]; ok {
+		session.PeakConcurrent = latest.PeakConcurrent
+	}
+	session.EndedAt = &now
+	if peakConcurrent > session.PeakConcurrent {
+		session.PeakConcurrent = peakConcurrent
 	}
 	s.data.StreamSessions[sessionID] = session
 	channel.CurrentSessionID = nil
-	channel.LiveState = "offline"
+	setLiveState(&channel, "offline", now)
 	channel.UpdatedAt = now
 	s.data.Channels[channelID] = channel
-
-	recording, recErr := s.createRecordingLocked(session, channel, now)
-	if recErr != nil {
-		s.data.StreamSessions[sessionID] = originalSession
-		s.data.Channels[channelID] = originalChannel
-		s.mu.Unlock()
-		return models.StreamSession{}, recErr
-	}
+	endedInvites := s.endCoStreamInvitesLocked(sessionID, now)
+
+	var recording models.Recording
+	if !discard {
+		var recErr error
+		recording, recErr = s.createRecordingLocked(session, channel, now, frame, outputBytes)
+		if recErr != nil {
+			s.data.StreamSessions[sessionID] = originalSession
+			s.data.Channels[channelID] = originalChannel
+			s.restoreCoStreamInvitesLocked(endedInvites)
+			s.mu.Unlock()
+			return models.StreamSession{}, recErr
+		}
+	}
+	var linkedClips []string
+	originalStorage, hadStorage := s.data.ChannelStorage[channelID]
 	if recording.ID != "" {
 		s.data.Recordings[recording.ID] = recording
+		s.adjustChannelStorageLocked(channelID, recording.SizeBytes, now)
+		linkedClips = s.linkSessionClipsLocked(sessionID, recording.ID)
 	}
 
 	if err := s.persist(); err != nil {
 		s.data.StreamSessions[sessionID] = originalSession
 		s.data.Channels[channelID] = originalChannel
+		s.restoreCoStreamInvitesLocked(endedInvites)
 		if recording.ID != "" {
 			delete(s.data.Recordings, recording.ID)
+			if hadStorage {
+				s.data.ChannelStorage[channelID] = originalStorage
+			} else {
+				delete(s.data.ChannelStorage, channelID)
+			}
+		}
+		for _, clipID := range linkedClips {
+			clip := s.data.ClipExports[clipID]
+			clip.RecordingID = ""
+			s.data.ClipExports[clipID] = clip
 		}
 		s.mu.Unlock()
 		return models.StreamSession{}, err
@@ -1553,7 +2305,7 @@ func (s *Storage) ListStreamSessions(channelID string) ([]models.StreamSession,
 	defer s.mu.RUnlock()
 
 	if _, ok := s.data.Channels[channelID]; !ok {
-		return nil, fmt.Errorf("channel %s not found", channelID)
+		return nil, notFound("channel", channelID)
 	}
 
 	sessions := make([]models.StreamSession, 0)
@@ -1563,7 +2315,10 @@ func (s *Storage) ListStreamSessions(channelID string) ([]models.StreamSession,
 		}
 	}
 	sort.Slice(sessions, func(i, j int) bool {
-		return sessions[i].StartedAt.After(sessions[j].StartedAt)
+		if !sessions[i].StartedAt.Equal(sessions[j].StartedAt) {
+			return sessions[i].StartedAt.After(sessions[j].StartedAt)
+		}
+		return sessions[i].ID < sessions[j].ID
 	})
 	return sessions, nil
 }
@@ -1589,18 +2344,19 @@ func (s *Storage) IngestHealth(ctx context.Context) []ingest.HealthStatus {
 	controller := s.ingestController
 	if controller == nil {
 		status := []ingest.HealthStatus{{Component: "ingest", Status: "disabled"}}
-		s.recordIngestHealth(status)
+		s.RecordIngestHealth(status)
 		return status
 	}
 	checks := controller.HealthChecks(ctx)
 	if len(checks) == 0 {
 		checks = []ingest.HealthStatus{{Component: "ingest", Status: "unknown"}}
 	}
-	s.recordIngestHealth(checks)
+	s.RecordIngestHealth(checks)
 	return checks
 }
 
-func (s *Storage) recordIngestHealth(statuses []ingest.HealthStatus) {
+// RecordIngestHealth replaces the snapshot returned by LastIngestHealth.
+func (s *Storage) RecordIngestHealth(statuses []ingest.HealthStatus) {
 	snapshot := append([]ingest.HealthStatus(nil), statuses...)
 	s.mu.Lock()
 	s.ingestHealth = snapshot
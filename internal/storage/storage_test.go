@@ -1,8 +1,10 @@
 package storage
 
 import (
+	"encoding/json"
 	"errors"
 	"testing"
+	"time"
 )
 
 func TestDeleteUserPersistFailureLeavesDataUntouched(t *testing.T) {
@@ -29,7 +31,7 @@ func TestDeleteUserPersistFailureLeavesDataUntouched(t *testing.T) {
 	if err != nil {
 		t.Fatalf("CreateChannel: %v", err)
 	}
-	if _, err := store.CreateChatMessage(channel.ID, target.ID, "hello"); err != nil {
+	if _, err := store.CreateChatMessage(channel.ID, target.ID, "hello", ""); err != nil {
 		t.Fatalf("CreateChatMessage: %v", err)
 	}
 
@@ -175,7 +177,7 @@ func TestDeleteChannelPersistFailureLeavesDataUntouched(t *testing.T) {
 	if _, err := store.StopStream(channel.ID, 10); err != nil {
 		t.Fatalf("StopStream: %v", err)
 	}
-	if _, err := store.CreateChatMessage(channel.ID, owner.ID, "hello"); err != nil {
+	if _, err := store.CreateChatMessage(channel.ID, owner.ID, "hello", ""); err != nil {
 		t.Fatalf("CreateChatMessage: %v", err)
 	}
 
@@ -202,6 +204,39 @@ func TestDeleteChannelPersistFailureLeavesDataUntouched(t *testing.T) {
 	}
 }
 
+func TestListChannelFollowersSkipsMissingUsers(t *testing.T) {
+	store := newTestStore(t)
+
+	owner, err := store.CreateUser(CreateUserParams{DisplayName: "Creator", Email: "creator@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser owner: %v", err)
+	}
+	viewer, err := store.CreateUser(CreateUserParams{DisplayName: "Viewer", Email: "viewer@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser viewer: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Workshop", "maker", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	if err := store.FollowChannel(viewer.ID, channel.ID); err != nil {
+		t.Fatalf("FollowChannel: %v", err)
+	}
+
+	// Older datasets can hold follows for users removed without cleanup.
+	store.mu.Lock()
+	store.data.Follows["ghost"] = map[string]time.Time{channel.ID: time.Now().UTC()}
+	store.mu.Unlock()
+
+	page, err := store.ListChannelFollowers(channel.ID, FollowerListOptions{})
+	if err != nil {
+		t.Fatalf("ListChannelFollowers: %v", err)
+	}
+	if page.Total != 1 || len(page.Followers) != 1 || page.Followers[0].UserID != viewer.ID {
+		t.Fatalf("expected only the existing follower, got %+v", page)
+	}
+}
+
 func TestFollowChannelLifecycle(t *testing.T) {
 	store := newTestStore(t)
 
@@ -224,8 +259,12 @@ func TestFollowChannelLifecycle(t *testing.T) {
 	if store.IsFollowingChannel(viewer.ID, channel.ID) {
 		t.Fatal("expected viewer to not follow channel")
 	}
-	if followed := store.ListFollowedChannelIDs(viewer.ID); followed != nil {
-		t.Fatalf("expected no followed channels, got %v", followed)
+	followed, err := store.ListFollowedChannelIDs(viewer.ID)
+	if err != nil {
+		t.Fatalf("ListFollowedChannelIDs: %v", err)
+	}
+	if followed == nil || len(followed) != 0 {
+		t.Fatalf("expected an empty followed channel list, got %#v", followed)
 	}
 
 	if err := store.FollowChannel(viewer.ID, channel.ID); err != nil {
@@ -240,7 +279,10 @@ func TestFollowChannelLifecycle(t *testing.T) {
 	if !store.IsFollowingChannel(viewer.ID, channel.ID) {
 		t.Fatal("expected viewer to follow channel")
 	}
-	followed := store.ListFollowedChannelIDs(viewer.ID)
+	followed, err = store.ListFollowedChannelIDs(viewer.ID)
+	if err != nil {
+		t.Fatalf("ListFollowedChannelIDs: %v", err)
+	}
 	if len(followed) != 1 || followed[0] != channel.ID {
 		t.Fatalf("unexpected followed list: %v", followed)
 	}
@@ -258,3 +300,33 @@ func TestFollowChannelLifecycle(t *testing.T) {
 		t.Fatal("expected viewer to not follow channel after unfollow")
 	}
 }
+
+func TestClaimedJobsSurviveRestart(t *testing.T) {
+	store := newTestStore(t)
+	job, err := store.EnqueueJob(EnqueueJobParams{Type: "test.restart", Payload: []byte(`{"id":"abc"}`)})
+	if err != nil {
+		t.Fatalf("EnqueueJob: %v", err)
+	}
+	if _, err := store.ClaimDueJobs("before-restart", 1, 50*time.Millisecond); err != nil {
+		t.Fatalf("ClaimDueJobs: %v", err)
+	}
+
+	reloaded, err := NewStorage(store.filePath)
+	if err != nil {
+		t.Fatalf("reload store: %v", err)
+	}
+	time.Sleep(100 * time.Millisecond)
+	jobs, err := reloaded.ClaimDueJobs("after-restart", 1, time.Minute)
+	if err != nil {
+		t.Fatalf("ClaimDueJobs after restart: %v", err)
+	}
+	if len(jobs) != 1 || jobs[0].ID != job.ID || jobs[0].Attempts != 2 {
+		t.Fatalf("expected abandoned job to be reclaimed, got %+v", jobs)
+	}
+	var payload struct {
+		ID string `json:"id"`
+	}
+	if err := json.Unmarshal(jobs[0].Payload, &payload); err != nil || payload.ID != "abc" {
+		t.Fatalf("unexpected payload %s (err %v)", jobs[0].Payload, err)
+	}
+}
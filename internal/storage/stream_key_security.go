@@ -0,0 +1,245 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/models"
+)
+
+// SecurityEventParams describes a security event to record against a
+// channel. A zero At records it at the current time.
+type SecurityEventParams struct {
+	ChannelID string
+	Kind      string
+	SourceIP  string
+	SessionID string
+	Detail    string
+	At        time.Time
+}
+
+// NotificationParams describes an in-app notification for a user. A zero At
+// creates it at the current time. Callers check the user's notification
+// preferences before creating one.
+type NotificationParams struct {
+	UserID    string
+	Category  string
+	Kind      string
+	ChannelID string
+	Message   string
+	Metadata  map[string]string
+	At        time.Time
+}
+
+func newSecurityEvent(params SecurityEventParams) (models.SecurityEvent, error) {
+	kind := strings.TrimSpace(params.Kind)
+	switch kind {
+	case models.SecurityEventPublishRejected, models.SecurityEventKeySourcesSpread, models.SecurityEventKeyRevoked, models.SecurityEventUploadInfected:
+	default:
+		return models.SecurityEvent{}, fmt.Errorf("unknown security event kind %q", params.Kind)
+	}
+	id, err := generateID()
+	if err != nil {
+		return models.SecurityEvent{}, err
+	}
+	at := params.At.UTC()
+	if params.At.IsZero() {
+		at = time.Now().UTC()
+	}
+	return models.SecurityEvent{
+		ID:        id,
+		ChannelID: strings.TrimSpace(params.ChannelID),
+		Kind:      kind,
+		SourceIP:  strings.TrimSpace(params.SourceIP),
+		SessionID: strings.TrimSpace(params.SessionID),
+		Detail:    strings.TrimSpace(params.Detail),
+		CreatedAt: at,
+	}, nil
+}
+
+func newNotification(params NotificationParams) (models.Notification, error) {
+	category := strings.TrimSpace(params.Category)
+	if _, ok := models.DefaultNotificationPreferences("").Category(category); !ok {
+		return models.Notification{}, fmt.Errorf("unknown notification category %q", params.Category)
+	}
+	kind := strings.TrimSpace(params.Kind)
+	if kind == "" {
+		return models.Notification{}, errors.New("notification kind is required")
+	}
+	message := strings.TrimSpace(params.Message)
+	if message == "" {
+		return models.Notification{}, errors.New("notification message is required")
+	}
+	id, err := generateID()
+	if err != nil {
+		return models.Notification{}, err
+	}
+	at := params.At.UTC()
+	if params.At.IsZero() {
+		at = time.Now().UTC()
+	}
+	return cloneNotification(models.Notification{
+		ID:        id,
+		UserID:    strings.TrimSpace(params.UserID),
+		Category:  category,
+		Kind:      kind,
+		ChannelID: strings.TrimSpace(params.ChannelID),
+		Message:   message,
+		Metadata:  params.Metadata,
+		CreatedAt: at,
+	}), nil
+}
+
+func cloneNotification(notification models.Notification) models.Notification {
+	if len(notification.Metadata) == 0 {
+		notification.Metadata = nil
+		return notification
+	}
+	metadata := make(map[string]string, len(notification.Metadata))
+	for key, value := range notification.Metadata {
+		metadata[key] = value
+	}
+	notification.Metadata = metadata
+	return notification
+}
+
+// sortSecurityEvents orders events newest first.
+func sortSecurityEvents(events []models.SecurityEvent) {
+	sort.Slice(events, func(i, j int) bool {
+		if !events[i].CreatedAt.Equal(events[j].CreatedAt) {
+			return events[i].CreatedAt.After(events[j].CreatedAt)
+		}
+		return events[i].ID < events[j].ID
+	})
+}
+
+// sortNotifications orders notifications newest first.
+func sortNotifications(notifications []models.Notification) {
+	sort.Slice(notifications, func(i, j int) bool {
+		if !notifications[i].CreatedAt.Equal(notifications[j].CreatedAt) {
+			return notifications[i].CreatedAt.After(notifications[j].CreatedAt)
+		}
+		return notifications[i].ID < notifications[j].ID
+	})
+}
+
+// ClaimPublishSource records source as the address the channel's live
+// session publishes from. The first address reported wins; a later publish
+// from the same address is accepted again, and one from any other address
+// returns the session with ErrPublishSourceConflict. An empty source claims
+// nothing. It returns ErrChannelNotLive when the channel has no session.
+func (s *Storage) ClaimPublishSource(channelID, source string) (models.StreamSession, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	channel, ok := s.data.Channels[channelID]
+	if !ok {
+		return models.StreamSession{}, notFound("channel", channelID)
+	}
+	if channel.CurrentSessionID == nil {
+		return models.StreamSession{}, ErrChannelNotLive
+	}
+	session, ok := s.data.StreamSessions[*channel.CurrentSessionID]
+	if !ok {
+		return models.StreamSession{}, fmt.Errorf("session %s missing", *channel.CurrentSessionID)
+	}
+	source = strings.TrimSpace(source)
+	switch {
+	case source == "" || session.PublishSource == source:
+		return session, nil
+	case session.PublishSource != "":
+		return session, ErrPublishSourceConflict
+	}
+	original := session
+	session.PublishSource = source
+	s.data.StreamSessions[session.ID] = session
+	if err := s.persist(); err != nil {
+		s.data.StreamSessions[session.ID] = original
+		return models.StreamSession{}, err
+	}
+	return session, nil
+}
+
+func (s *Storage) CreateSecurityEvent(params SecurityEventParams) (models.SecurityEvent, error) {
+	event, err := newSecurityEvent(params)
+	if err != nil {
+		return models.SecurityEvent{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data.Channels[event.ChannelID]; !ok {
+		return models.SecurityEvent{}, notFound("channel", event.ChannelID)
+	}
+	if s.data.SecurityEvents == nil {
+		s.data.SecurityEvents = make(map[string]models.SecurityEvent)
+	}
+	s.data.SecurityEvents[event.ID] = event
+	if err := s.persist(); err != nil {
+		delete(s.data.SecurityEvents, event.ID)
+		return models.SecurityEvent{}, err
+	}
+	return event, nil
+}
+
+// ListSecurityEvents returns the channel's security events recorded at or
+// after since, newest first.
+func (s *Storage) ListSecurityEvents(channelID string, since time.Time) ([]models.SecurityEvent, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.data.Channels[channelID]; !ok {
+		return nil, notFound("channel", channelID)
+	}
+	events := make([]models.SecurityEvent, 0)
+	for _, event := range s.data.SecurityEvents {
+		if event.ChannelID == channelID && !event.CreatedAt.Before(since) {
+			events = append(events, event)
+		}
+	}
+	sortSecurityEvents(events)
+	return events, nil
+}
+
+func (s *Storage) CreateNotification(params NotificationParams) (models.Notification, error) {
+	notification, err := newNotification(params)
+	if err != nil {
+		return models.Notification{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if _, ok := s.data.Users[notification.UserID]; !ok {
+		return models.Notification{}, notFound("user", notification.UserID)
+	}
+	if s.data.Notifications == nil {
+		s.data.Notifications = make(map[string]models.Notification)
+	}
+	s.data.Notifications[notification.ID] = notification
+	if err := s.persist(); err != nil {
+		delete(s.data.Notifications, notification.ID)
+		return models.Notification{}, err
+	}
+	return cloneNotification(notification), nil
+}
+
+// ListNotifications returns the user's newest notifications, at most limit
+// of them. A non-positive limit returns all of them.
+func (s *Storage) ListNotifications(userID string, limit int) ([]models.Notification, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if _, ok := s.data.Users[userID]; !ok {
+		return nil, notFound("user", userID)
+	}
+	notifications := make([]models.Notification, 0)
+	for _, notification := range s.data.Notifications {
+		if notification.UserID == userID {
+			notifications = append(notifications, cloneNotification(notification))
+		}
+	}
+	sortNotifications(notifications)
+	if limit > 0 && len(notifications) > limit {
+		notifications = notifications[:limit]
+	}
+	return notifications, nil
+}
@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"bitriver-live/internal/models"
+)
+
+// StreamLimitError reports that a channel cannot start because its owner
+// already streams on Limit channels. Active lists the channels holding the
+// slots.
+type StreamLimitError struct {
+	Limit  int
+	Active []StreamSlot
+}
+
+func (e *StreamLimitError) Error() string {
+	names := make([]string, 0, len(e.Active))
+	for _, slot := range e.Active {
+		names = append(names, fmt.Sprintf("%q (%s)", slot.Title, slot.ChannelID))
+	}
+	return fmt.Sprintf("concurrent stream limit of %d reached; already streaming on %s", e.Limit, strings.Join(names, ", "))
+}
+
+// Is makes StreamLimitError match ErrStreamLimitReached and
+// ErrPreconditionFailed.
+func (e *StreamLimitError) Is(target error) bool {
+	return target == ErrStreamLimitReached || target == ErrPreconditionFailed
+}
+
+// effectiveStreamLimit is the limit a user is held to: their override when
+// an admin set one, otherwise the platform default. Zero is unlimited.
+func effectiveStreamLimit(override *int, platform int) int {
+	if override != nil {
+		return *override
+	}
+	if platform < 0 {
+		return 0
+	}
+	return platform
+}
+
+// normalizeStreamLimit validates an admin limit override. Nil clears the
+// override and zero lifts the limit for the user.
+func normalizeStreamLimit(limit *int) (*int, error) {
+	if limit == nil {
+		return nil, nil
+	}
+	if *limit < 0 {
+		return nil, invalid("maxConcurrentStreams", "maxConcurrentStreams must be zero or greater")
+	}
+	value := *limit
+	return &value, nil
+}
+
+// checkStreamLimit returns a *StreamLimitError when active already fills
+// limit.
+func checkStreamLimit(limit int, active []StreamSlot) error {
+	if limit <= 0 || len(active) < limit {
+		return nil
+	}
+	return &StreamLimitError{Limit: limit, Active: active}
+}
+
+// streamSlotsLocked returns the owner's channels, other than exclude, that
+// hold a slot, ordered by channel ID.
+func (s *Storage) streamSlotsLocked(ownerID, exclude string) []StreamSlot {
+	slots := make([]StreamSlot, 0)
+	for id, channel := range s.data.Channels {
+		if channel.OwnerID != ownerID || id == exclude || channel.CurrentSessionID == nil {
+			continue
+		}
+		slots = append(slots, StreamSlot{ChannelID: id, Title: channel.Title, LiveState: channel.LiveState})
+	}
+	sort.Slice(slots, func(i, j int) bool { return slots[i].ChannelID < slots[j].ChannelID })
+	return slots
+}
+
+// checkStreamLimitLocked refuses to start channel when its owner is at
+// their limit. It runs under s.mu in the same critical section that
+// reserves the session, so two starts on different channels cannot both
+// take the last slot.
+func (s *Storage) checkStreamLimitLocked(channel models.Channel) error {
+	owner, ok := s.data.Users[channel.OwnerID]
+	if !ok {
+		return nil
+	}
+	limit := effectiveStreamLimit(owner.MaxConcurrentStreams, s.streamLimit)
+	if limit <= 0 {
+		return nil
+	}
+	return checkStreamLimit(limit, s.streamSlotsLocked(owner.ID, channel.ID))
+}
+
+// StreamSlotUsage reports the user's concurrent streams and the limit they
+// are held to.
+func (s *Storage) StreamSlotUsage(userID string) (StreamSlotReport, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	user, ok := s.data.Users[userID]
+	if !ok {
+		return StreamSlotReport{}, notFound("user", userID)
+	}
+	report := StreamSlotReport{
+		UserID: user.ID,
+		Limit:  effectiveStreamLimit(user.MaxConcurrentStreams, s.streamLimit),
+		Active: s.streamSlotsLocked(user.ID, ""),
+	}
+	if user.MaxConcurrentStreams != nil {
+		override := *user.MaxConcurrentStreams
+		report.LimitOverride = &override
+	}
+	return report, nil
+}
+
+// SetUserStreamLimit sets the admin override of the user's concurrent
+// stream limit. Nil returns the user to the platform default and zero lifts
+// the limit. Lowering the limit does not stop streams already running; it
+// only refuses new starts.
+func (s *Storage) SetUserStreamLimit(userID string, limit *int) (models.User, error) {
+	override, err := normalizeStreamLimit(limit)
+	if err != nil {
+		return models.User{}, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	user, ok := s.data.Users[userID]
+	if !ok {
+		return models.User{}, notFound("user", userID)
+	}
+	previous := user
+	user.MaxConcurrentStreams = override
+	s.data.Users[userID] = user
+	if err := s.persist(); err != nil {
+		s.data.Users[userID] = previous
+		return models.User{}, err
+	}
+	return user, nil
+}
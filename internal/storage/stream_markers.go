@@ -0,0 +1,137 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"bitriver-live/internal/models"
+)
+
+const (
+	// MaxStreamMarkersPerSession caps how many markers one live session can
+	// hold.
+	MaxStreamMarkersPerSession = 100
+	// MaxStreamMarkerLabelLength is the longest marker label, in characters.
+	MaxStreamMarkerLabelLength = 140
+)
+
+// normalizeStreamMarkerParams validates params and fills in the marker time.
+func normalizeStreamMarkerParams(params StreamMarkerParams) (StreamMarkerParams, error) {
+	params.UserID = strings.TrimSpace(params.UserID)
+	params.Label = strings.TrimSpace(params.Label)
+	if params.UserID == "" {
+		return StreamMarkerParams{}, invalid("userId", "user id is required")
+	}
+	if params.Label == "" {
+		return StreamMarkerParams{}, invalid("label", "label is required")
+	}
+	if utf8.RuneCountInString(params.Label) > MaxStreamMarkerLabelLength {
+		return StreamMarkerParams{}, invalid("label", "label must be %d characters or fewer", MaxStreamMarkerLabelLength)
+	}
+	if params.At.IsZero() {
+		params.At = time.Now()
+	}
+	params.At = params.At.UTC()
+	return params, nil
+}
+
+// newStreamMarker builds the marker row for params in session. The offset is
+// whole seconds since the session started, never negative.
+func newStreamMarker(session models.StreamSession, params StreamMarkerParams) (models.StreamMarker, error) {
+	id, err := generateID()
+	if err != nil {
+		return models.StreamMarker{}, err
+	}
+	offset := int(params.At.Sub(session.StartedAt) / time.Second)
+	if offset < 0 {
+		offset = 0
+	}
+	return models.StreamMarker{
+		ID:            id,
+		ChannelID:     session.ChannelID,
+		SessionID:     session.ID,
+		Label:         params.Label,
+		OffsetSeconds: offset,
+		CreatedBy:     params.UserID,
+		CreatedAt:     params.At,
+	}, nil
+}
+
+// sortStreamMarkers orders markers by offset, oldest first.
+func sortStreamMarkers(markers []models.StreamMarker) {
+	sort.SliceStable(markers, func(i, j int) bool {
+		if markers[i].OffsetSeconds != markers[j].OffsetSeconds {
+			return markers[i].OffsetSeconds < markers[j].OffsetSeconds
+		}
+		if !markers[i].CreatedAt.Equal(markers[j].CreatedAt) {
+			return markers[i].CreatedAt.Before(markers[j].CreatedAt)
+		}
+		return markers[i].ID < markers[j].ID
+	})
+}
+
+// CreateStreamMarker marks params.At in the channel's live session. It
+// returns ErrChannelNotLive when nothing is live and ErrStreamMarkerLimit
+// once the session holds MaxStreamMarkersPerSession markers.
+func (s *Storage) CreateStreamMarker(channelID string, params StreamMarkerParams) (models.StreamMarker, error) {
+	params, err := normalizeStreamMarkerParams(params)
+	if err != nil {
+		return models.StreamMarker{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	channel, ok := s.data.Channels[channelID]
+	if !ok {
+		return models.StreamMarker{}, notFound("channel", channelID)
+	}
+	if _, ok := s.data.Users[params.UserID]; !ok {
+		return models.StreamMarker{}, notFound("user", params.UserID)
+	}
+	if channel.CurrentSessionID == nil {
+		return models.StreamMarker{}, ErrChannelNotLive
+	}
+	session, ok := s.data.StreamSessions[*channel.CurrentSessionID]
+	if !ok {
+		return models.StreamMarker{}, fmt.Errorf("session %s missing", *channel.CurrentSessionID)
+	}
+	count := 0
+	for _, marker := range s.data.StreamMarkers {
+		if marker.SessionID == session.ID {
+			count++
+		}
+	}
+	if count >= MaxStreamMarkersPerSession {
+		return models.StreamMarker{}, ErrStreamMarkerLimit
+	}
+
+	marker, err := newStreamMarker(session, params)
+	if err != nil {
+		return models.StreamMarker{}, err
+	}
+	snapshot := cloneDataset(s.data)
+	s.data.StreamMarkers[marker.ID] = marker
+	if err := s.persist(); err != nil {
+		s.data = snapshot
+		return models.StreamMarker{}, err
+	}
+	return marker, nil
+}
+
+// ListStreamMarkers returns the markers of a session ordered by offset. An
+// unknown session has no markers.
+func (s *Storage) ListStreamMarkers(sessionID string) ([]models.StreamMarker, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	markers := make([]models.StreamMarker, 0)
+	for _, marker := range s.data.StreamMarkers {
+		if marker.SessionID == sessionID {
+			markers = append(markers, marker)
+		}
+	}
+	sortStreamMarkers(markers)
+	return markers, nil
+}
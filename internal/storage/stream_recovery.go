@@ -0,0 +1,178 @@
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"bitriver-live/internal/ingest"
+	"bitriver-live/internal/models"
+)
+
+// StreamRecovery describes a stuck start that RecoverStream cleared.
+type StreamRecovery struct {
+	Channel models.Channel
+	// SessionID is the session the abandoned start had reserved.
+	SessionID string
+	// JobIDs lists the transcoder jobs recorded for that session, usually
+	// none since jobs are only recorded once a boot succeeds.
+	JobIDs []string
+	// ShutdownErr is set when the ingest teardown failed. The channel has
+	// been reset regardless so the creator can go live again.
+	ShutdownErr error
+}
+
+// setLiveState moves channel to state and keeps StartingSince in step:
+// stamped on entering "starting", cleared in every other state.
+func setLiveState(channel *models.Channel, state string, now time.Time) {
+	if state == "starting" {
+		if channel.LiveState != "starting" || channel.StartingSince == nil {
+			since := now
+			channel.StartingSince = &since
+		}
+	} else {
+		channel.StartingSince = nil
+	}
+	channel.LiveState = state
+}
+
+// checkStartAllowed reports why a channel holding a session cannot start
+// another: a start younger than timeout is still in progress, an older one
+// is stuck, and anything else is already live.
+func checkStartAllowed(channel models.Channel, now time.Time, timeout time.Duration) error {
+	if channel.CurrentSessionID == nil {
+		return nil
+	}
+	if channel.LiveState != "starting" {
+		return ErrChannelAlreadyLive
+	}
+	if startIsStuck(channel, now, timeout) {
+		return ErrStreamStartStuck
+	}
+	return ErrStreamStarting
+}
+
+// startIsStuck reports whether channel has been starting for at least
+// timeout. A starting channel without StartingSince predates the column and
+// counts as stuck.
+func startIsStuck(channel models.Channel, now time.Time, timeout time.Duration) bool {
+	if channel.CurrentSessionID == nil || channel.LiveState != "starting" {
+		return false
+	}
+	if channel.StartingSince == nil {
+		return true
+	}
+	return now.Sub(*channel.StartingSince) >= timeout
+}
+
+// bootIngest boots ingest through controller, retrying transient failures
+// up to attempts times with interval between them. Each attempt runs under
+// timeout within ctx, and no attempt starts once ctx is done. An error from
+// a boot that ran out of ctx's time wraps ErrStreamStartTimedOut: the
+// controller rolls back what it knows it created, but a service may still be
+// acting on a request it never answered.
+func bootIngest(ctx context.Context, controller ingest.Controller, params ingest.BootParams, attempts int, timeout, interval time.Duration) (ingest.BootResult, error) {
+	if attempts <= 0 {
+		attempts = 1
+	}
+	var bootErr error
+	for attempt := 0; attempt < attempts; attempt++ {
+		attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+		boot, err := controller.BootStream(attemptCtx, params)
+		cancel()
+		if err == nil {
+			return boot, nil
+		}
+		bootErr = err
+		if ctx.Err() != nil || (errors.Is(err, context.DeadlineExceeded) && boundByCaller(ctx, timeout)) {
+			return ingest.BootResult{}, fmt.Errorf("%w: boot ingest: %w", ErrStreamStartTimedOut, err)
+		}
+		if isPermanentBootError(err) {
+			break
+		}
+		if attempt < attempts-1 && interval > 0 {
+			timer := time.NewTimer(interval)
+			select {
+			case <-timer.C:
+			case <-ctx.Done():
+				timer.Stop()
+				return ingest.BootResult{}, fmt.Errorf("%w: boot ingest: %w", ErrStreamStartTimedOut, err)
+			}
+		}
+	}
+	return ingest.BootResult{}, fmt.Errorf("boot ingest: %w", bootErr)
+}
+
+// boundByCaller reports whether what is left of ctx's deadline no longer
+// fits a full attempt of timeout, so a timed-out boot is down to the
+// caller's budget and retrying it would only be cut short again. The
+// controller keeps part of that budget back for rollback, so such a boot
+// gives up before ctx itself expires.
+func boundByCaller(ctx context.Context, timeout time.Duration) bool {
+	deadline, ok := ctx.Deadline()
+	return ok && time.Until(deadline) <= timeout
+}
+
+// shutdownIngest tears the session's ingest down through controller within
+// timeout and ctx. An error from a teardown that ctx cut short wraps
+// ErrStreamStopTimedOut.
+func shutdownIngest(ctx context.Context, controller ingest.Controller, channelID, sessionID string, jobIDs []string, timeout time.Duration) (int64, error) {
+	shutdownCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	outputBytes, err := ingest.ShutdownStreamWithOutput(shutdownCtx, controller, channelID, sessionID, jobIDs)
+	if err != nil {
+		if ctx.Err() != nil {
+			return 0, fmt.Errorf("%w: shutdown ingest: %w", ErrStreamStopTimedOut, err)
+		}
+		return 0, fmt.Errorf("shutdown ingest: %w", err)
+	}
+	return outputBytes, nil
+}
+
+// RecoverStream clears a start that has been stuck for longer than the
+// starting timeout. It resets the channel to offline, then tears down
+// whatever ingest the abandoned session may have created. A StartStream call
+// that finishes booting afterwards notices the reset and discards its
+// session.
+func (s *Storage) RecoverStream(channelID string) (StreamRecovery, error) {
+	controller := s.ingestController
+	if controller == nil {
+		return StreamRecovery{}, ErrIngestControllerUnavailable
+	}
+
+	s.mu.Lock()
+	channel, ok := s.data.Channels[channelID]
+	if !ok {
+		s.mu.Unlock()
+		return StreamRecovery{}, notFound("channel", channelID)
+	}
+	now := time.Now().UTC()
+	if !startIsStuck(channel, now, s.startingTimeout) {
+		s.mu.Unlock()
+		return StreamRecovery{}, ErrStreamNotStuck
+	}
+	recovery := StreamRecovery{SessionID: *channel.CurrentSessionID}
+	if session, exists := s.data.StreamSessions[recovery.SessionID]; exists {
+		recovery.JobIDs = append([]string{}, session.IngestJobIDs...)
+	}
+	original := channel
+	channel.CurrentSessionID = nil
+	setLiveState(&channel, "offline", now)
+	channel.UpdatedAt = now
+	s.data.Channels[channelID] = channel
+	if err := s.persist(); err != nil {
+		s.data.Channels[channelID] = original
+		s.mu.Unlock()
+		return StreamRecovery{}, err
+	}
+	s.mu.Unlock()
+	recovery.Channel = channel
+
+	ctx, cancel := context.WithTimeout(context.Background(), normalizeIngestTimeout(s.ingestTimeout))
+	defer cancel()
+	if err := controller.ShutdownStream(ctx, channelID, recovery.SessionID, recovery.JobIDs); err != nil {
+		recovery.ShutdownErr = fmt.Errorf("shutdown ingest: %w", err)
+	}
+	return recovery, nil
+}
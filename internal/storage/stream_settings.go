@@ -0,0 +1,73 @@
+package storage
+
+import (
+	"strconv"
+	"strings"
+
+	"bitriver-live/internal/ingest"
+	"bitriver-live/internal/models"
+)
+
+// newStreamSettings snapshots the settings a session starts with: the
+// renditions the creator asked for, the ladder the ingest backend built, and
+// the channel's language.
+func newStreamSettings(requested []string, boot ingest.BootResult, language string) *models.StreamSettings {
+	settings := &models.StreamSettings{
+		RequestedRenditions: append([]string{}, requested...),
+		Ladder:              make([]models.LadderRendition, 0, len(boot.Renditions)),
+		LatencyMode:         models.LatencyModeForPlaybackURL(boot.PlaybackURL),
+		Language:            language,
+	}
+	if len(boot.PlaybackEndpoints) > 0 && boot.PlaybackEndpoints[0].Protocol == models.PlaybackProtocolWebRTC {
+		settings.LatencyMode = models.LatencyModeUltraLow
+	}
+	for _, rendition := range boot.Renditions {
+		settings.Ladder = append(settings.Ladder, models.LadderRendition{Name: rendition.Name, Bitrate: rendition.Bitrate})
+	}
+	return settings
+}
+
+func cloneStreamSettings(settings *models.StreamSettings) *models.StreamSettings {
+	if settings == nil {
+		return nil
+	}
+	cloned := *settings
+	cloned.RequestedRenditions = append([]string{}, settings.RequestedRenditions...)
+	cloned.Ladder = append([]models.LadderRendition{}, settings.Ladder...)
+	return &cloned
+}
+
+func clonePlaybackEndpoints(endpoints []models.PlaybackEndpoint) []models.PlaybackEndpoint {
+	if endpoints == nil {
+		return nil
+	}
+	return append([]models.PlaybackEndpoint{}, endpoints...)
+}
+
+// addStreamSettingsMetadata copies the session settings worth keeping with a
+// recording into its metadata. Ladder rungs are written as name@bitrate.
+func addStreamSettingsMetadata(metadata map[string]string, settings *models.StreamSettings) {
+	if settings == nil {
+		return
+	}
+	if settings.LatencyMode != "" {
+		metadata["latencyMode"] = settings.LatencyMode
+	}
+	if settings.Language != "" {
+		metadata["language"] = settings.Language
+	}
+	if len(settings.RequestedRenditions) > 0 {
+		metadata["requestedRenditions"] = strings.Join(settings.RequestedRenditions, ",")
+	}
+	if len(settings.Ladder) > 0 {
+		rungs := make([]string, 0, len(settings.Ladder))
+		for _, rung := range settings.Ladder {
+			if rung.Bitrate > 0 {
+				rungs = append(rungs, rung.Name+"@"+strconv.Itoa(rung.Bitrate))
+				continue
+			}
+			rungs = append(rungs, rung.Name)
+		}
+		metadata["ladder"] = strings.Join(rungs, ",")
+	}
+}
@@ -3,11 +3,16 @@ package storage
 import (
 	"context"
 	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
 	"reflect"
+	"strings"
 	"testing"
 	"time"
 
 	"bitriver-live/internal/ingest"
+	"bitriver-live/internal/models"
 )
 
 // bootResponse stores canned ingest boot outcomes for tests.
@@ -28,15 +33,19 @@ type fakeIngestController struct {
 	bootDefault     ingest.BootResult
 	bootErr         error
 	bootCalls       int
+	lastBoot        ingest.BootParams
 	shutdownErr     error
 	shutdownCalls   []shutdownCall
 	healthResponses [][]ingest.HealthStatus
 	healthCalls     int
+	frame           ingest.Frame
+	frameJobIDs     []string
 }
 
 func (f *fakeIngestController) BootStream(ctx context.Context, params ingest.BootParams) (ingest.BootResult, error) {
 	idx := f.bootCalls
 	f.bootCalls++
+	f.lastBoot = params
 	if idx < len(f.bootResponses) {
 		resp := f.bootResponses[idx]
 		if resp.err != nil {
@@ -59,6 +68,14 @@ func (f *fakeIngestController) ShutdownStream(ctx context.Context, channelID, se
 	return nil
 }
 
+func (f *fakeIngestController) CaptureFrame(ctx context.Context, jobID string) (ingest.Frame, error) {
+	f.frameJobIDs = append(f.frameJobIDs, jobID)
+	if len(f.frame.Data) == 0 {
+		return ingest.Frame{}, ingest.ErrFrameUnavailable
+	}
+	return f.frame, nil
+}
+
 func (f *fakeIngestController) HealthChecks(ctx context.Context) []ingest.HealthStatus {
 	if len(f.healthResponses) == 0 {
 		return []ingest.HealthStatus{{Component: "fake", Status: "ok"}}
@@ -76,6 +93,47 @@ func (f *fakeIngestController) TranscodeUpload(ctx context.Context, params inges
 	return ingest.UploadTranscodeResult{PlaybackURL: params.SourceURL}, nil
 }
 
+// TestStartStreamBootsEnabledRestreamTargets checks that StartStream hands
+// the controller the enabled targets with their keys opened, and skips a
+// target whose key no longer opens instead of failing the stream.
+func TestStartStreamBootsEnabledRestreamTargets(t *testing.T) {
+	key := []byte("0123456789abcdef0123456789abcdef")
+	controller := &fakeIngestController{}
+	store := newTestStoreWithController(t, controller, WithSecretKey(key))
+	owner, err := store.CreateUser(CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Simulcast", "", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	enabled, err := store.CreateRestreamTarget(channel.ID, RestreamTargetParams{Label: "On", URL: "rtmp://a.example.com/app", StreamKey: "key-a", Enabled: true})
+	if err != nil {
+		t.Fatalf("CreateRestreamTarget: %v", err)
+	}
+	if _, err := store.CreateRestreamTarget(channel.ID, RestreamTargetParams{Label: "Off", URL: "rtmp://b.example.com/app", StreamKey: "key-b"}); err != nil {
+		t.Fatalf("CreateRestreamTarget: %v", err)
+	}
+	corrupt, err := store.CreateRestreamTarget(channel.ID, RestreamTargetParams{Label: "Corrupt", URL: "rtmp://c.example.com/app", StreamKey: "key-c", Enabled: true})
+	if err != nil {
+		t.Fatalf("CreateRestreamTarget: %v", err)
+	}
+	store.mu.Lock()
+	moved := store.data.RestreamTargets[corrupt.ID]
+	moved.StreamKeyCiphertext = enabled.StreamKeyCiphertext
+	store.data.RestreamTargets[corrupt.ID] = moved
+	store.mu.Unlock()
+
+	if _, err := store.StartStream(channel.ID, []string{"720p"}); err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	want := []ingest.RestreamTarget{{ID: enabled.ID, URL: "rtmp://a.example.com/app", StreamKey: "key-a"}}
+	if !reflect.DeepEqual(controller.lastBoot.Restreams, want) {
+		t.Fatalf("expected restreams %+v, got %+v", want, controller.lastBoot.Restreams)
+	}
+}
+
 func TestCreateChannelAndStartStopStream(t *testing.T) {
 	store := newTestStore(t)
 	user, err := store.CreateUser(CreateUserParams{
@@ -230,6 +288,65 @@ func TestStorageStopStreamTimesOutWhenIngestBlocks(t *testing.T) {
 	}
 }
 
+func TestStartStreamContextLeavesChannelStartingOnDeadline(t *testing.T) {
+	controller := &timeoutIngestController{bootBlock: true}
+	store := newTestStoreWithController(t, controller, WithIngestRetries(3, 0))
+
+	user, err := store.CreateUser(CreateUserParams{DisplayName: "Creator", Email: "creator@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(user.ID, "Budget", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	start := time.Now()
+	if _, err := store.StartStreamContext(ctx, channel.ID, []string{"720p"}); !errors.Is(err, ErrStreamStartTimedOut) {
+		t.Fatalf("expected ErrStreamStartTimedOut, got %v", err)
+	}
+	if time.Since(start) > 200*time.Millisecond {
+		t.Fatalf("StartStreamContext outlived its context: %v", time.Since(start))
+	}
+	// The boot may have left resources behind, so the channel waits for
+	// RecoverStream rather than going back offline.
+	updated, ok := store.GetChannel(channel.ID)
+	if !ok || updated.LiveState != "starting" || updated.CurrentSessionID == nil {
+		t.Fatalf("expected channel to stay starting, got %+v", updated)
+	}
+}
+
+func TestStopStreamContextKeepsChannelLiveOnDeadline(t *testing.T) {
+	controller := &timeoutIngestController{bootResult: ingest.BootResult{PlaybackURL: "https://playback.example"}}
+	store := newTestStoreWithController(t, controller)
+
+	user, err := store.CreateUser(CreateUserParams{DisplayName: "Creator", Email: "creator@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(user.ID, "Budget", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	session, err := store.StartStream(channel.ID, []string{"720p"})
+	if err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+
+	controller.shutdownBlock = true
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Millisecond)
+	defer cancel()
+	if _, err := store.StopStreamContext(ctx, channel.ID, 5); !errors.Is(err, ErrStreamStopTimedOut) {
+		t.Fatalf("expected ErrStreamStopTimedOut, got %v", err)
+	}
+	updated, ok := store.GetChannel(channel.ID)
+	if !ok || updated.LiveState != "live" || updated.CurrentSessionID == nil || *updated.CurrentSessionID != session.ID {
+		t.Fatalf("expected channel to stay live on session %s, got %+v", session.ID, updated)
+	}
+}
+
 func TestRotateChannelStreamKey(t *testing.T) {
 	store := newTestStore(t)
 
@@ -254,8 +371,11 @@ func TestRotateChannelStreamKey(t *testing.T) {
 		if !ok {
 			t.Fatalf("channel %s missing from persisted dataset", channel.ID)
 		}
-		if updated.StreamKey == originalKey {
-			t.Fatalf("expected persisted stream key to differ from original")
+		if updated.StreamKey != "" {
+			t.Fatalf("expected plaintext stream key not to be persisted")
+		}
+		if updated.StreamKeyHash == "" || updated.StreamKeyHash == HashStreamKey(originalKey) {
+			t.Fatalf("expected persisted stream key hash to differ from original")
 		}
 		return nil
 	}
@@ -279,8 +399,8 @@ func TestRotateChannelStreamKey(t *testing.T) {
 	if !ok {
 		t.Fatalf("channel %s not found after rotation", channel.ID)
 	}
-	if fetched.StreamKey != rotated.StreamKey {
-		t.Fatalf("expected fetched stream key %s, got %s", rotated.StreamKey, fetched.StreamKey)
+	if !StreamKeyMatches(fetched, rotated.StreamKey) {
+		t.Fatalf("expected fetched channel to match rotated stream key %s", rotated.StreamKey)
 	}
 }
 
@@ -311,8 +431,49 @@ func TestRotateChannelStreamKeyPersistFailure(t *testing.T) {
 	if !ok {
 		t.Fatalf("channel %s not found after failed rotation", channel.ID)
 	}
-	if fetched.StreamKey != channel.StreamKey {
-		t.Fatalf("expected stream key %s to remain after failure, got %s", channel.StreamKey, fetched.StreamKey)
+	if !StreamKeyMatches(fetched, channel.StreamKey) {
+		t.Fatalf("expected stream key %s to remain after failure", channel.StreamKey)
+	}
+}
+
+func TestStorageHashesPlaintextStreamKeysOnLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	const legacyKey = "ABCDEF0123456789ABCDEF0123456789ABCDEF0123456789"
+	legacy := `{
+  "users": {"owner": {"id": "owner", "displayName": "Owner", "email": "owner@example.com", "createdAt": "2024-01-01T00:00:00Z"}},
+  "channels": {"chan": {"id": "chan", "ownerId": "owner", "streamKey": "` + legacyKey + `", "title": "Legacy", "tags": [], "liveState": "offline", "createdAt": "2024-01-01T00:00:00Z", "updatedAt": "2024-01-01T00:00:00Z"}}
+}`
+	if err := os.WriteFile(path, []byte(legacy), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	store, err := NewStorage(path)
+	if err != nil {
+		t.Fatalf("NewStorage: %v", err)
+	}
+	channel, ok := store.GetChannel("chan")
+	if !ok {
+		t.Fatal("expected legacy channel to load")
+	}
+	if channel.StreamKey != "" {
+		t.Fatalf("expected plaintext key to be dropped, got %q", channel.StreamKey)
+	}
+	if !StreamKeyMatches(channel, legacyKey) {
+		t.Fatal("expected legacy key to match the migrated hash")
+	}
+	if channel.StreamKeyHint != "ABCD…6789" {
+		t.Fatalf("expected hint ABCD…6789, got %q", channel.StreamKeyHint)
+	}
+	if found, ok := store.FindChannelByStreamKeyHash(HashStreamKey(legacyKey)); !ok || found.ID != "chan" {
+		t.Fatalf("expected lookup by hash to find legacy channel, got %+v (ok=%v)", found, ok)
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if strings.Contains(string(raw), legacyKey) {
+		t.Fatal("expected migrated store file to no longer contain the plaintext key")
 	}
 }
 
@@ -368,6 +529,41 @@ func TestStartStreamPersistsIngestMetadata(t *testing.T) {
 	}
 }
 
+func TestStreamSettingsSurviveReload(t *testing.T) {
+	fake := &fakeIngestController{bootDefault: ingest.BootResult{
+		PlaybackURL: "https://cdn/master.m3u8",
+		Renditions:  []ingest.Rendition{{Name: "720p", ManifestURL: "https://cdn/720p.m3u8", Bitrate: 3000}},
+	}}
+	store := newTestStoreWithController(t, fake)
+	user, err := store.CreateUser(CreateUserParams{DisplayName: "Creator", Email: "creator@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(user.ID, "Tech", "science", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	session, err := store.StartStream(channel.ID, []string{"720p"})
+	if err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+
+	reloaded, err := NewStorage(store.filePath)
+	if err != nil {
+		t.Fatalf("reload storage: %v", err)
+	}
+	current, ok := reloaded.CurrentStreamSession(channel.ID)
+	if !ok {
+		t.Fatal("expected live session after reload")
+	}
+	if !reflect.DeepEqual(current.Settings, session.Settings) {
+		t.Fatalf("expected settings %+v after reload, got %+v", session.Settings, current.Settings)
+	}
+	if current.Settings.LatencyMode != models.LatencyModeLow {
+		t.Fatalf("expected low-latency mode for HLS playback, got %q", current.Settings.LatencyMode)
+	}
+}
+
 func TestStartStreamRetriesBootFailures(t *testing.T) {
 	fake := &fakeIngestController{bootResponses: []bootResponse{
 		{err: errors.New("transcoder offline")},
@@ -392,6 +588,30 @@ func TestStartStreamRetriesBootFailures(t *testing.T) {
 	}
 }
 
+func TestStartStreamDoesNotRetryOutOfStorage(t *testing.T) {
+	fake := &fakeIngestController{bootResponses: []bootResponse{
+		{err: fmt.Errorf("start jobs: %w", ingest.ErrTranscoderOutOfStorage)},
+		{result: ingest.BootResult{OriginURL: "http://origin/hls"}},
+	}}
+	store := newTestStoreWithController(t, fake, WithIngestRetries(3, 0))
+
+	user, err := store.CreateUser(CreateUserParams{DisplayName: "Creator", Email: "creator@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(user.ID, "Tech", "science", []string{"hardware"})
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+
+	if _, err := store.StartStream(channel.ID, []string{"1080p"}); !errors.Is(err, ingest.ErrTranscoderOutOfStorage) {
+		t.Fatalf("expected ErrTranscoderOutOfStorage, got %v", err)
+	}
+	if fake.bootCalls != 1 {
+		t.Fatalf("expected a single boot attempt, got %d", fake.bootCalls)
+	}
+}
+
 func TestStartStreamFailureRollsBackState(t *testing.T) {
 	fake := &fakeIngestController{bootResponses: []bootResponse{
 		{err: errors.New("network error")},
@@ -423,6 +643,170 @@ func TestStartStreamFailureRollsBackState(t *testing.T) {
 	}
 }
 
+// gatedIngestController holds BootStream until release is closed so tests
+// can observe a channel while it is starting.
+type gatedIngestController struct {
+	fakeIngestController
+	booting chan struct{}
+	release chan struct{}
+}
+
+func (g *gatedIngestController) BootStream(ctx context.Context, params ingest.BootParams) (ingest.BootResult, error) {
+	select {
+	case g.booting <- struct{}{}:
+	default:
+	}
+	<-g.release
+	return g.fakeIngestController.BootStream(ctx, params)
+}
+
+func TestStartStreamDebouncesAndRecoversStuckStart(t *testing.T) {
+	gate := &gatedIngestController{
+		fakeIngestController: fakeIngestController{bootDefault: ingest.BootResult{JobIDs: []string{"job-late"}}},
+		booting:              make(chan struct{}, 1),
+		release:              make(chan struct{}),
+	}
+	timeout := 50 * time.Millisecond
+	store := newTestStoreWithController(t, gate, WithStartingTimeout(timeout))
+
+	user, err := store.CreateUser(CreateUserParams{DisplayName: "Creator", Email: "creator@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(user.ID, "Stuck", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+
+	firstStart := make(chan error, 1)
+	go func() {
+		_, err := store.StartStream(channel.ID, []string{"720p"})
+		firstStart <- err
+	}()
+	<-gate.booting
+
+	starting, _ := store.GetChannel(channel.ID)
+	if starting.LiveState != "starting" || starting.StartingSince == nil || starting.CurrentSessionID == nil {
+		t.Fatalf("expected a starting channel with StartingSince, got %+v", starting)
+	}
+	if _, err := store.StartStream(channel.ID, []string{"720p"}); !errors.Is(err, ErrStreamStarting) {
+		t.Fatalf("expected ErrStreamStarting while the first start boots, got %v", err)
+	}
+	if _, err := store.RecoverStream(channel.ID); !errors.Is(err, ErrStreamNotStuck) {
+		t.Fatalf("expected ErrStreamNotStuck before the timeout, got %v", err)
+	}
+
+	time.Sleep(timeout)
+	if _, err := store.StartStream(channel.ID, []string{"720p"}); !errors.Is(err, ErrStreamStartStuck) {
+		t.Fatalf("expected ErrStreamStartStuck after the timeout, got %v", err)
+	}
+	recovery, err := store.RecoverStream(channel.ID)
+	if err != nil {
+		t.Fatalf("RecoverStream: %v", err)
+	}
+	if recovery.SessionID != *starting.CurrentSessionID || recovery.ShutdownErr != nil {
+		t.Fatalf("expected recovery of session %s, got %+v", *starting.CurrentSessionID, recovery)
+	}
+	recovered := recovery.Channel
+	if recovered.LiveState != "offline" || recovered.StartingSince != nil || recovered.CurrentSessionID != nil {
+		t.Fatalf("expected the channel reset to offline, got %+v", recovered)
+	}
+	if len(gate.shutdownCalls) != 1 || gate.shutdownCalls[0].sessionID != recovery.SessionID {
+		t.Fatalf("expected ingest shut down for the stuck session, got %+v", gate.shutdownCalls)
+	}
+
+	close(gate.release)
+	if err := <-firstStart; !errors.Is(err, ErrStreamStartAborted) {
+		t.Fatalf("expected the late boot to be aborted, got %v", err)
+	}
+	if len(gate.shutdownCalls) != 2 || !reflect.DeepEqual(gate.shutdownCalls[1].jobIDs, []string{"job-late"}) {
+		t.Fatalf("expected the late boot's jobs shut down, got %+v", gate.shutdownCalls)
+	}
+	if after, _ := store.GetChannel(channel.ID); after.LiveState != "offline" || after.CurrentSessionID != nil {
+		t.Fatalf("expected the late boot to leave the channel offline, got %+v", after)
+	}
+
+	if _, err := store.StartStream(channel.ID, []string{"720p"}); err != nil {
+		t.Fatalf("StartStream after recovery: %v", err)
+	}
+	if live, _ := store.GetChannel(channel.ID); live.LiveState != "live" || live.StartingSince != nil {
+		t.Fatalf("expected a live channel without StartingSince, got %+v", live)
+	}
+}
+
+func TestStartStreamHoldsOwnersToTheirStreamLimit(t *testing.T) {
+	gate := &gatedIngestController{
+		booting: make(chan struct{}, 1),
+		release: make(chan struct{}),
+	}
+	store := newTestStoreWithController(t, gate, WithMaxConcurrentStreams(1))
+
+	owner, err := store.CreateUser(CreateUserParams{DisplayName: "Creator", Email: "creator@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	other, err := store.CreateUser(CreateUserParams{DisplayName: "Other", Email: "other@example.com"})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	first, err := store.CreateChannel(owner.ID, "First", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	second, err := store.CreateChannel(owner.ID, "Second", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	unrelated, err := store.CreateChannel(other.ID, "Unrelated", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+
+	// A channel still booting ingest already holds its slot.
+	firstStart := make(chan error, 1)
+	go func() {
+		_, err := store.StartStream(first.ID, []string{"720p"})
+		firstStart <- err
+	}()
+	<-gate.booting
+	_, err = store.StartStream(second.ID, []string{"720p"})
+	var limitErr *StreamLimitError
+	if !errors.As(err, &limitErr) || limitErr.Limit != 1 || len(limitErr.Active) != 1 || limitErr.Active[0].ChannelID != first.ID || limitErr.Active[0].LiveState != "starting" {
+		t.Fatalf("expected the starting channel to hold the only slot, got %v", err)
+	}
+	if !strings.Contains(err.Error(), first.ID) {
+		t.Fatalf("expected the error to name the streaming channel, got %q", err.Error())
+	}
+	close(gate.release)
+	if err := <-firstStart; err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+
+	// The default applies per owner.
+	if _, err := store.StartStream(unrelated.ID, []string{"720p"}); err != nil {
+		t.Fatalf("expected another owner's channel to start, got %v", err)
+	}
+
+	// An override beats the platform default.
+	two := 2
+	if _, err := store.SetUserStreamLimit(owner.ID, &two); err != nil {
+		t.Fatalf("SetUserStreamLimit: %v", err)
+	}
+	if _, err := store.StartStream(second.ID, []string{"720p"}); err != nil {
+		t.Fatalf("expected the override to allow a second stream, got %v", err)
+	}
+	report, err := store.StreamSlotUsage(owner.ID)
+	if err != nil {
+		t.Fatalf("StreamSlotUsage: %v", err)
+	}
+	if report.Limit != 2 || len(report.Active) != 2 {
+		t.Fatalf("unexpected stream slot report %+v", report)
+	}
+	if report, err := store.StreamSlotUsage(other.ID); err != nil || report.Limit != 1 || report.LimitOverride != nil {
+		t.Fatalf("expected the platform default without an override, got %+v (err %v)", report, err)
+	}
+}
+
 func TestStopStreamInvokesShutdown(t *testing.T) {
 	fake := &fakeIngestController{bootResponses: []bootResponse{{result: ingest.BootResult{
 		JobIDs: []string{"job-123"},
@@ -492,7 +876,7 @@ func TestDeleteChannelRemovesArtifacts(t *testing.T) {
 	if _, err := store.StopStream(channel.ID, 10); err != nil {
 		t.Fatalf("StopStream: %v", err)
 	}
-	if _, err := store.CreateChatMessage(channel.ID, owner.ID, "hello"); err != nil {
+	if _, err := store.CreateChatMessage(channel.ID, owner.ID, "hello", ""); err != nil {
 		t.Fatalf("CreateChatMessage: %v", err)
 	}
 	if err := store.FollowChannel(viewer.ID, channel.ID); err != nil {
@@ -514,7 +898,11 @@ func TestDeleteChannelRemovesArtifacts(t *testing.T) {
 	if followers := store.CountFollowers(channel.ID); followers != 0 {
 		t.Fatalf("expected follower count reset, got %d", followers)
 	}
-	if following := store.ListFollowedChannelIDs(viewer.ID); len(following) != 0 {
+	following, err := store.ListFollowedChannelIDs(viewer.ID)
+	if err != nil {
+		t.Fatalf("ListFollowedChannelIDs: %v", err)
+	}
+	if len(following) != 0 {
 		t.Fatalf("expected viewer follow list to be cleared, got %v", following)
 	}
 }
@@ -0,0 +1,110 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	_ "image/jpeg"
+	_ "image/png"
+	"time"
+
+	"bitriver-live/internal/ingest"
+	"bitriver-live/internal/models"
+)
+
+// frameCaptureTimeout bounds how long a stream stop or preview request waits
+// for the transcoder to hand over a still frame.
+const frameCaptureTimeout = 5 * time.Second
+
+// captureSessionFrame asks the ingest controller for a still frame from the
+// first of jobIDs that has one, within ctx. Frames are best-effort:
+// controllers that cannot capture frames and jobs without output simply
+// report no frame.
+func captureSessionFrame(ctx context.Context, controller ingest.Controller, timeout time.Duration, jobIDs []string) (ingest.Frame, bool) {
+	capturer, ok := controller.(ingest.FrameCapturer)
+	if !ok || len(jobIDs) == 0 {
+		return ingest.Frame{}, false
+	}
+	timeout = normalizeIngestTimeout(timeout)
+	if timeout > frameCaptureTimeout {
+		timeout = frameCaptureTimeout
+	}
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	for _, jobID := range jobIDs {
+		frame, err := capturer.CaptureFrame(ctx, jobID)
+		if err == nil && len(frame.Data) > 0 {
+			return frame, true
+		}
+		if ctx.Err() != nil {
+			break
+		}
+	}
+	return ingest.Frame{}, false
+}
+
+// attachRecordingThumbnail uploads frame as the recording's thumbnail,
+// records its object key, public URL, and pixel dimensions, and counts its
+// size towards the recording's SizeBytes. Frames that are not decodable
+// images are skipped so viewers never receive a broken thumbnail.
+func attachRecordingThumbnail(client objectStorageClient, cfg ObjectStorageConfig, recording *models.Recording, frame ingest.Frame) error {
+	if len(frame.Data) == 0 {
+		return nil
+	}
+	config, format, err := image.DecodeConfig(bytes.NewReader(frame.Data))
+	if err != nil {
+		return nil
+	}
+	thumbID, err := generateID()
+	if err != nil {
+		return fmt.Errorf("generate thumbnail id: %w", err)
+	}
+	contentType := "image/" + format
+	thumbKey := buildObjectKey("recordings", recording.ID, "thumbnails", thumbID+"."+thumbnailExtension(format))
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.uploadTimeout(len(frame.Data)))
+	ref, err := client.Upload(ctx, thumbKey, contentType, frame.Data)
+	cancel()
+	if err != nil {
+		return fmt.Errorf("upload thumbnail: %w", err)
+	}
+	recording.SizeBytes += int64(len(frame.Data))
+	if ref.Key != "" {
+		recording.Metadata[thumbnailMetadataKey(thumbID)] = ref.Key
+	}
+	recording.Thumbnails = append(recording.Thumbnails, models.RecordingThumbnail{
+		ID:          thumbID,
+		RecordingID: recording.ID,
+		URL:         ref.URL,
+		Width:       config.Width,
+		Height:      config.Height,
+		CreatedAt:   recording.CreatedAt,
+	})
+	return nil
+}
+
+func thumbnailExtension(format string) string {
+	if format == "jpeg" {
+		return "jpg"
+	}
+	return format
+}
+
+// ChannelPreview returns a still frame from the channel's live transcoder jobs
+// for directory cards. It returns ingest.ErrFrameUnavailable when the channel
+// is offline or no frame has been captured yet.
+func (s *Storage) ChannelPreview(channelID string) (ingest.Frame, error) {
+	return channelPreview(s.ingestController, s.ingestTimeout, s.CurrentStreamSession, channelID)
+}
+
+func channelPreview(controller ingest.Controller, timeout time.Duration, current func(string) (models.StreamSession, bool), channelID string) (ingest.Frame, error) {
+	session, live := current(channelID)
+	if !live {
+		return ingest.Frame{}, ingest.ErrFrameUnavailable
+	}
+	frame, ok := captureSessionFrame(context.Background(), controller, timeout, session.IngestJobIDs)
+	if !ok {
+		return ingest.Frame{}, ingest.ErrFrameUnavailable
+	}
+	return frame, nil
+}
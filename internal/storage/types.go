@@ -28,14 +28,34 @@ const (
 	// tip message payload.
 	MaxTipMessageLength = 512
 
+	// MaxGiftSubscriptionCount defines the maximum number of subscriptions a
+	// viewer can gift in a single request.
+	MaxGiftSubscriptionCount = 100
+
 	// MaxChatMessageLength defines the maximum number of characters allowed for a
 	// chat message.
 	MaxChatMessageLength = 500
+	// MaxChatReplayPageSize caps how many chat messages a single replay
+	// window request returns.
+	MaxChatReplayPageSize = 500
+
+	// APITokenPrefix marks personal access token secrets so the auth layer can
+	// distinguish them from session tokens.
+	APITokenPrefix = "brl_pat_"
+	// MaxAPITokenNameLength defines the maximum number of characters allowed
+	// for a personal access token name.
+	MaxAPITokenNameLength = 64
+
+	APITokenScopeRead          = "read"
+	APITokenScopeChat          = "chat"
+	APITokenScopeManageChannel = "manage-channel"
 
 	ChatReportStatusOpen     = "open"
 	ChatReportStatusResolved = "resolved"
 
-	duplicateTipReferenceError = "pq: duplicate key value violates unique constraint \"tips_reference_unique\""
+	ChatAppealStatusOpen     = "open"
+	ChatAppealStatusAccepted = "accepted"
+	ChatAppealStatusRejected = "rejected"
 )
 
 var (
@@ -43,32 +63,200 @@ var (
 	// operations cannot be performed because no ingest controller has been
 	// configured.
 	ErrIngestControllerUnavailable = errors.New("ingest controller unavailable")
+	// ErrChannelNotLive indicates that an operation needs a live stream and
+	// the channel has none.
+	ErrChannelNotLive = precondition("channel is not live")
+	// ErrChannelAlreadyLive indicates that a stream cannot start because the
+	// channel is already live.
+	ErrChannelAlreadyLive = precondition("channel already live")
+	// ErrStreamSessionEnded indicates that a stream session has already
+	// ended and no longer takes viewer updates.
+	ErrStreamSessionEnded = precondition("stream session has ended")
+	// ErrCoStreamInviteClosed indicates that a co-stream invite was answered
+	// after it stopped being pending.
+	ErrCoStreamInviteClosed = precondition("co-stream invite is no longer pending")
+	// ErrCoStreamGuestLimit indicates that a session already has
+	// MaxCoStreamGuests active invites.
+	ErrCoStreamGuestLimit = precondition("co-stream guest limit reached")
+	// ErrCoStreamGuestBusy indicates that the guest channel is live itself,
+	// already co-streaming, or already invited to the session.
+	ErrCoStreamGuestBusy = precondition("guest channel is live or already co-streaming")
+	// ErrRecordingPublished indicates that a premiere was scheduled or
+	// cancelled for a recording that is already public.
+	ErrRecordingPublished = precondition("recording is already published")
+	// ErrRecordingPremiereScheduled indicates that a recording scheduled to
+	// premiere was published directly; it stays hidden until the premiere.
+	ErrRecordingPremiereScheduled = precondition("recording is scheduled to premiere")
+	// ErrStreamStarting indicates that another StartStream call is still
+	// booting ingest for the channel.
+	ErrStreamStarting = precondition("stream is already starting")
+	// ErrStreamStartStuck indicates that the channel has been starting for
+	// longer than the starting timeout and needs RecoverStream.
+	ErrStreamStartStuck = precondition("stream start is stuck")
+	// ErrStreamNotStuck indicates that RecoverStream was called for a channel
+	// that is not starting, or has not been starting for long enough.
+	ErrStreamNotStuck = precondition("stream start is not stuck")
+	// ErrStreamStartAborted indicates that the channel was recovered while
+	// StartStream was booting ingest, so the new session was discarded.
+	ErrStreamStartAborted = errors.New("stream start was aborted by recovery")
+	// ErrStreamStartTimedOut indicates that StartStreamContext's context
+	// ended before ingest finished booting. The boot may have left resources
+	// behind, so the channel stays starting until RecoverStream tears the
+	// session down.
+	ErrStreamStartTimedOut = errors.New("stream start timed out while ingest was booting")
+	// ErrStreamStopTimedOut indicates that StopStreamContext's context ended
+	// before ingest was torn down. The channel stays live and the stop can be
+	// retried.
+	ErrStreamStopTimedOut = errors.New("stream stop timed out while ingest was shutting down")
+	// ErrPublishSourceConflict indicates that a publish arrived from a
+	// different address than the one the live session is publishing from.
+	ErrPublishSourceConflict = errors.New("stream key is already publishing from another source")
+	// ErrStreamMarkerLimit indicates that a session already holds
+	// MaxStreamMarkersPerSession markers.
+	ErrStreamMarkerLimit = errors.New("stream marker limit reached")
+	// ErrRestreamTargetLimit indicates that a channel already has
+	// MaxRestreamTargetsPerChannel restream targets.
+	ErrRestreamTargetLimit = errors.New("restream target limit reached")
+	// ErrRestreamTargetNotFound indicates that a channel has no restream
+	// target with the requested ID.
+	ErrRestreamTargetNotFound = notFound("restream target", "")
+	// ErrScheduleEntryLimit indicates that the channel already has
+	// MaxScheduleEntriesPerChannel schedule entries.
+	ErrScheduleEntryLimit = errors.New("schedule entry limit reached")
+	// ErrScheduleEntryNotFound indicates that the channel has no schedule
+	// entry with the requested ID.
+	ErrScheduleEntryNotFound = notFound("schedule entry", "")
+	// ErrChatPinNotFound indicates that the channel has no pinned chat
+	// message.
+	ErrChatPinNotFound = notFound("chat pin", "")
+	// ErrStorageQuotaExceeded indicates that a channel has no room left in
+	// its storage quota for a new upload, or that a recording cannot be
+	// published until space is freed.
+	ErrStorageQuotaExceeded = errors.New("channel storage quota exceeded")
+	// ErrStreamLimitReached indicates that a channel cannot start because
+	// its owner already streams on as many channels as their concurrent
+	// stream limit allows. The error returned is a *StreamLimitError naming
+	// those channels.
+	ErrStreamLimitReached = errors.New("concurrent stream limit reached")
+	// ErrNotChatRestricted indicates that a chat appeal was filed by a user
+	// who is neither banned nor timed out in the channel.
+	ErrNotChatRestricted = precondition("no chat restriction to appeal")
+	// ErrChatAppealOpen indicates that the user already has an open appeal in
+	// the channel.
+	ErrChatAppealOpen = conflict("appeal", "an appeal is already open")
+	// ErrChatAppealNotFound indicates that a channel has no appeal with the
+	// requested ID.
+	ErrChatAppealNotFound = notFound("chat appeal", "")
+	// ErrChatAppealResolved indicates that the appeal was already accepted or
+	// rejected.
+	ErrChatAppealResolved = precondition("chat appeal already resolved")
+	// ErrInvalidPlaybackQuality indicates that a playback preference named an
+	// unknown default quality.
+	ErrInvalidPlaybackQuality = invalid("defaultQuality", "invalid playback quality")
+
+	// ErrBadgeNotFound indicates that no badge definition has the requested
+	// slug.
+	ErrBadgeNotFound = notFound("badge", "")
+	// ErrBadgeExists indicates that a badge definition already uses the
+	// requested slug.
+	ErrBadgeExists = conflict("slug", "badge already exists")
+	// ErrBadgeInUse indicates that a badge definition cannot be deleted while
+	// users still hold it.
+	ErrBadgeInUse = precondition("badge is still granted")
+	// ErrBadgeGrantNotFound indicates that the user does not hold the badge.
+	ErrBadgeGrantNotFound = notFound("badge grant", "")
+
+	// ErrFeatureFlagNotFound indicates that no feature flag has the
+	// requested key.
+	ErrFeatureFlagNotFound = notFound("feature flag", "")
+	// ErrFeatureFlagExists indicates that a feature flag already uses the
+	// requested key.
+	ErrFeatureFlagExists = conflict("key", "feature flag already exists")
+
+	// ErrDonationAddressNotFound indicates that the profile lists no donation
+	// address matching the request.
+	ErrDonationAddressNotFound = notFound("donation address", "")
+	// ErrDonationAddressUnverifiable indicates that addresses of the currency
+	// cannot be proven with a wallet signature.
+	ErrDonationAddressUnverifiable = errors.New("donation addresses of this currency cannot be verified")
+
+	// ErrGrantExpiryTooSoon indicates that a time-limited moderator or editor
+	// grant would expire less than models.MinChannelGrantDuration from now.
+	ErrGrantExpiryTooSoon = invalid("expiresAt", "grant must last at least %s", models.MinChannelGrantDuration)
 
 	ErrInvalidCredentials       = errors.New("invalid credentials")
 	ErrPasswordLoginUnsupported = errors.New("account does not support password login")
+	// ErrUserDeactivated indicates that the account has been deprovisioned
+	// and may no longer sign in.
+	ErrUserDeactivated = errors.New("account is deactivated")
+
+	ErrInvalidAPIToken  = errors.New("invalid or expired access token")
+	ErrAPITokenNotFound = notFound("access token", "")
 )
 
 type dataset struct {
-	Users               map[string]models.User          `json:"users"`
-	OAuthAccounts       map[string]models.OAuthAccount  `json:"oauthAccounts"`
-	Channels            map[string]models.Channel       `json:"channels"`
-	StreamSessions      map[string]models.StreamSession `json:"streamSessions"`
-	ChatMessages        map[string]models.ChatMessage   `json:"chatMessages"`
-	ChatBans            map[string]map[string]time.Time `json:"chatBans"`
-	ChatTimeouts        map[string]map[string]time.Time `json:"chatTimeouts"`
-	ChatBanActors       map[string]map[string]string    `json:"chatBanActors"`
-	ChatBanReasons      map[string]map[string]string    `json:"chatBanReasons"`
-	ChatTimeoutActors   map[string]map[string]string    `json:"chatTimeoutActors"`
-	ChatTimeoutReasons  map[string]map[string]string    `json:"chatTimeoutReasons"`
-	ChatTimeoutIssuedAt map[string]map[string]time.Time `json:"chatTimeoutIssuedAt"`
-	ChatReports         map[string]models.ChatReport    `json:"chatReports"`
-	Tips                map[string]models.Tip           `json:"tips"`
-	Subscriptions       map[string]models.Subscription  `json:"subscriptions"`
-	Profiles            map[string]models.Profile       `json:"profiles"`
-	Follows             map[string]map[string]time.Time `json:"follows"`
-	Recordings          map[string]models.Recording     `json:"recordings"`
-	Uploads             map[string]models.Upload        `json:"uploads"`
-	ClipExports         map[string]models.ClipExport    `json:"clipExports"`
+	Users               map[string]models.User                     `json:"users"`
+	OAuthAccounts       map[string]models.OAuthAccount             `json:"oauthAccounts"`
+	APITokens           map[string]models.APIToken                 `json:"apiTokens"`
+	Channels            map[string]models.Channel                  `json:"channels"`
+	StreamSessions      map[string]models.StreamSession            `json:"streamSessions"`
+	ChatMessages        map[string]models.ChatMessage              `json:"chatMessages"`
+	ChatBans            map[string]map[string]time.Time            `json:"chatBans"`
+	ChatTimeouts        map[string]map[string]time.Time            `json:"chatTimeouts"`
+	ChatBanActors       map[string]map[string]string               `json:"chatBanActors"`
+	ChatBanReasons      map[string]map[string]string               `json:"chatBanReasons"`
+	ChatTimeoutActors   map[string]map[string]string               `json:"chatTimeoutActors"`
+	ChatTimeoutReasons  map[string]map[string]string               `json:"chatTimeoutReasons"`
+	ChatTimeoutIssuedAt map[string]map[string]time.Time            `json:"chatTimeoutIssuedAt"`
+	ChatReports         map[string]models.ChatReport               `json:"chatReports"`
+	ModerationActions   map[string]models.ModerationAction         `json:"moderationActions"`
+	Tips                map[string]models.Tip                      `json:"tips"`
+	Subscriptions       map[string]models.Subscription             `json:"subscriptions"`
+	Profiles            map[string]models.Profile                  `json:"profiles"`
+	Follows             map[string]map[string]time.Time            `json:"follows"`
+	ChannelEditors      map[string]map[string]models.ChannelEditor `json:"channelEditors"`
+	Recordings          map[string]models.Recording                `json:"recordings"`
+	Uploads             map[string]models.Upload                   `json:"uploads"`
+	ClipExports         map[string]models.ClipExport               `json:"clipExports"`
+	Jobs                map[string]Job                             `json:"jobs"`
+	// NotificationPreferences is keyed by user ID and only holds users who
+	// changed their preferences.
+	NotificationPreferences map[string]models.NotificationPreferences `json:"notificationPreferences"`
+	StreamMarkers           map[string]models.StreamMarker            `json:"streamMarkers"`
+	RestreamTargets         map[string]models.RestreamTarget          `json:"restreamTargets"`
+	ChatAppeals             map[string]models.ChatAppeal              `json:"chatAppeals"`
+	// PlaybackPreferences is keyed by user ID and only holds users who
+	// changed their preferences.
+	PlaybackPreferences map[string]models.PlaybackPreferences `json:"playbackPreferences"`
+	// BadgeDefinitions is keyed by slug.
+	BadgeDefinitions map[string]models.BadgeDefinition `json:"badgeDefinitions"`
+	// BadgeGrants is keyed by user ID, then badge slug.
+	BadgeGrants map[string]map[string]models.BadgeGrant `json:"badgeGrants"`
+	// ScheduleEntries is keyed by entry ID.
+	ScheduleEntries map[string]models.ScheduleEntry `json:"scheduleEntries"`
+	// ScheduleFeedTokens is keyed by user ID.
+	ScheduleFeedTokens map[string]models.ScheduleFeedToken `json:"scheduleFeedTokens"`
+	// ChannelStorage is keyed by channel ID.
+	ChannelStorage map[string]models.ChannelStorage `json:"channelStorage"`
+	// ChatPins is keyed by channel ID.
+	ChatPins map[string]models.ChatPin `json:"chatPins"`
+	// ChannelModerators is keyed by channel ID, then user ID.
+	ChannelModerators map[string]map[string]models.ChannelModerator `json:"channelModerators"`
+	// ChatSequences holds the last chat sequence number issued per channel
+	// ID.
+	ChatSequences map[string]int64 `json:"chatSequences"`
+	// PlatformStats is keyed by PlatformStats.Day.
+	PlatformStats map[string]PlatformStats `json:"platformStats"`
+	// SecurityEvents is keyed by event ID.
+	SecurityEvents map[string]models.SecurityEvent `json:"securityEvents"`
+	// Notifications is keyed by notification ID.
+	Notifications map[string]models.Notification `json:"notifications"`
+	// CoStreamInvites is keyed by invite ID.
+	CoStreamInvites map[string]models.CoStreamInvite `json:"coStreamInvites"`
+	// FeatureFlags is keyed by flag key.
+	FeatureFlags map[string]models.FeatureFlag `json:"featureFlags"`
+	// ChannelSlugRedirects is keyed by the earlier slug.
+	ChannelSlugRedirects map[string]models.ChannelSlugRedirect `json:"channelSlugRedirects"`
 }
 
 type Storage struct {
@@ -81,12 +269,23 @@ type Storage struct {
 	ingestMaxAttempts   int
 	ingestRetryInterval time.Duration
 	ingestTimeout       time.Duration
+	startingTimeout     time.Duration
 	ingestHealth        []ingest.HealthStatus
 	ingestHealthUpdated time.Time
 	recordingRetention  RecordingRetentionPolicy
+	storageQuota        int64
+	streamLimit         int
 	objectStorage       ObjectStorageConfig
 	objectClient        objectStorageClient
 	retentionNow        func() time.Time
+	grantNow            func() time.Time
+	secretKey           []byte
+	encryptionKeys      []EncryptionKey
+	secrets             *secretSealer
+	// chatClientMessages indexes recent chat messages by
+	// chat.ClientMessageKey to their ID. It is rebuilt on load and not
+	// persisted; lookups verify the message still exists.
+	chatClientMessages map[string]string
 }
 
 // RecordingRetentionPolicy specifies how long recordings are kept before being
@@ -108,13 +307,37 @@ type ObjectStorageConfig struct {
 	Prefix         string
 	LifecycleDays  int
 	PublicEndpoint string
+	// RestrictProfileImages requires avatar and banner URLs to live under
+	// PublicEndpoint, so profiles cannot point at arbitrary hosts.
+	RestrictProfileImages bool
+	// RequestTimeout bounds each attempt for deletes and small metadata
+	// writes such as manifests and thumbnails.
 	RequestTimeout time.Duration
+	// UploadTimeout bounds each attempt for large object uploads, including
+	// individual multipart parts.
+	UploadTimeout time.Duration
+	// MultipartThreshold is the payload size in bytes above which uploads
+	// are split into multipart uploads.
+	MultipartThreshold int64
+	// MultipartPartSize is the size in bytes of each multipart part. S3
+	// requires every part except the last to be at least 5 MiB.
+	MultipartPartSize int64
+	// MaxRetries caps how many times an idempotent request is retried after
+	// a transient failure.
+	MaxRetries int
+	// RetryBackoff is the initial delay between retries; it doubles after
+	// every attempt up to maxObjectStorageRetryBackoff.
+	RetryBackoff time.Duration
 }
 
 type objectStorageClient interface {
 	Enabled() bool
 	Upload(ctx context.Context, key, contentType string, body []byte) (objectReference, error)
 	Delete(ctx context.Context, key string) error
+	// PresignGet returns a URL that lets anyone holding it download key
+	// until ttl has passed, so objects in a private bucket can be handed
+	// to players.
+	PresignGet(key string, ttl time.Duration) (string, error)
 }
 
 type objectReference struct {
@@ -122,7 +345,40 @@ type objectReference struct {
 	URL string
 }
 
-const defaultObjectStorageRequestTimeout = 30 * time.Second
+const (
+	defaultObjectStorageRequestTimeout     = 30 * time.Second
+	defaultObjectStorageUploadTimeout      = 10 * time.Minute
+	defaultObjectStorageMultipartThreshold = 64 << 20
+	defaultObjectStorageMultipartPartSize  = 16 << 20
+	defaultObjectStorageMaxRetries         = 3
+	defaultObjectStorageRetryBackoff       = 200 * time.Millisecond
+	maxObjectStorageRetryBackoff           = 5 * time.Second
+	// maxObjectStoragePresignTTL is the longest lifetime SigV4 allows for
+	// a presigned URL.
+	maxObjectStoragePresignTTL = 7 * 24 * time.Hour
+	// smallObjectStoragePayload is the largest payload treated as a metadata
+	// write governed by RequestTimeout instead of UploadTimeout.
+	smallObjectStoragePayload = 1 << 20
+)
+
+// ChatMessageRangeParams selects chat messages posted in [Start, End) for
+// replaying chat alongside a recording. Messages are returned oldest first;
+// AfterCreatedAt/AfterID resume after the last message of a previous page.
+type ChatMessageRangeParams struct {
+	ChannelID      string
+	Start          time.Time
+	End            time.Time
+	AfterCreatedAt time.Time
+	AfterID        string
+	Limit          int
+}
+
+// ChatterStats counts the distinct users who chatted in a channel since a
+// point in time, and how many of them chatted there for the first time.
+type ChatterStats struct {
+	UniqueChatters    int
+	FirstTimeChatters int
+}
 
 // ClipExportParams captures the request to generate a recording clip.
 type ClipExportParams struct {
@@ -131,12 +387,148 @@ type ClipExportParams struct {
 	EndSeconds   int
 }
 
+// LiveClipParams captures a viewer's request to clip the last
+// DurationSeconds of a live stream.
+type LiveClipParams struct {
+	UserID          string
+	Title           string
+	DurationSeconds int
+}
+
+// ChatPinParams captures a pin request. Exactly one of MessageID, naming a
+// message already in the channel's chat, or Content, free text, is set.
+type ChatPinParams struct {
+	ActorID   string
+	MessageID string
+	Content   string
+}
+
+// ReportParams captures a viewer's report. SubjectID names the reported
+// message, session, or recording; it defaults to ChannelID for channel
+// reports. Claimant is required for, and only kept on, copyright reports.
+type ReportParams struct {
+	ChannelID   string
+	ReporterID  string
+	SubjectType string
+	SubjectID   string
+	Category    string
+	Reason      string
+	EvidenceURL string
+	Claimant    *models.ReportClaimant
+}
+
+// ReportFilter narrows ListReports. Empty fields match every report.
+type ReportFilter struct {
+	ChannelID       string
+	SubjectType     string
+	Category        string
+	IncludeResolved bool
+}
+
+// StreamMarkerParams captures a request to mark the current moment of a
+// live stream. A zero At marks the time the marker is stored.
+type StreamMarkerParams struct {
+	UserID string
+	Label  string
+	At     time.Time
+}
+
+// RestreamTargetParams captures a new restream target. StreamKey is the
+// plaintext key; it is sealed before it is stored.
+type RestreamTargetParams struct {
+	Label     string
+	URL       string
+	StreamKey string
+	Enabled   bool
+}
+
+// RestreamTargetUpdate captures changes to a restream target. Nil fields
+// are left unchanged; a non-nil StreamKey replaces the stored key.
+type RestreamTargetUpdate struct {
+	Label     *string
+	URL       *string
+	StreamKey *string
+	Enabled   *bool
+}
+
+// ScheduleEntryParams captures a new schedule entry. StartsAt is the first
+// occurrence; TimeZone defaults to UTC and Recurrence to a single stream.
+type ScheduleEntryParams struct {
+	Title           string
+	Category        string
+	StartsAt        time.Time
+	DurationMinutes int
+	TimeZone        string
+	Recurrence      string
+	RepeatUntil     *time.Time
+}
+
+// ScheduleEntryUpdate captures changes to a schedule entry. Nil fields are
+// left unchanged; a zero RepeatUntil clears the end of a recurrence.
+type ScheduleEntryUpdate struct {
+	Title           *string
+	Category        *string
+	StartsAt        *time.Time
+	DurationMinutes *int
+	TimeZone        *string
+	Recurrence      *string
+	RepeatUntil     *time.Time
+}
+
+// BadgeDefinitionParams captures a new global badge definition.
+type BadgeDefinitionParams struct {
+	Slug    string
+	Label   string
+	IconURL string
+}
+
+// BadgeDefinitionUpdate captures changes to a badge definition. Nil fields
+// are left unchanged; the slug cannot change because grants refer to it.
+type BadgeDefinitionUpdate struct {
+	Label   *string
+	IconURL *string
+}
+
+// FeatureFlagParams captures a new feature flag.
+type FeatureFlagParams struct {
+	Key            string
+	Description    string
+	Enabled        bool
+	RolloutPercent int
+	AllowUserIDs   []string
+	DenyUserIDs    []string
+}
+
+// FeatureFlagUpdate captures changes to a feature flag. Nil fields are left
+// unchanged, and non-nil lists replace the stored ones. The key cannot
+// change because it is what bucketing hashes.
+type FeatureFlagUpdate struct {
+	Description    *string
+	Enabled        *bool
+	RolloutPercent *int
+	AllowUserIDs   *[]string
+	DenyUserIDs    *[]string
+}
+
+// RestreamTargetStatus is the live state of one of a channel's restreams.
+// Target is the zero value when the target was deleted after the stream
+// started.
+type RestreamTargetStatus struct {
+	TargetID  string
+	Target    models.RestreamTarget
+	State     string
+	Restarts  int
+	LastError string
+}
+
 // CreateUploadParams captures the information required to store an uploaded asset.
+// ContentHash is the hex-encoded SHA-256 of the source media, when known.
 type CreateUploadParams struct {
 	ChannelID   string
 	Title       string
 	Filename    string
 	SizeBytes   int64
+	ContentHash string
 	Metadata    map[string]string
 	PlaybackURL string
 }
@@ -151,17 +543,146 @@ type UploadUpdate struct {
 	Metadata    map[string]string
 	Error       *string
 	CompletedAt *time.Time
+	// OutputBytes records the transcoded output size reported by the
+	// transcoder and moves the channel's storage usage to match.
+	OutputBytes *int64
+	// Steps replaces the upload's pipeline steps. Progress is derived from
+	// them unless the update also sets Progress.
+	Steps []models.UploadStep
+}
+
+// ChannelStorageReport describes a channel's storage usage. QuotaBytes is
+// the quota in effect, zero when unlimited, and QuotaOverride the admin
+// override it comes from, if any. Items lists what the usage is made of,
+// largest first.
+type ChannelStorageReport struct {
+	ChannelID     string
+	UsedBytes     int64
+	QuotaBytes    int64
+	QuotaOverride *int64
+	Items         []ChannelStorageItem
+}
+
+// StreamSlot is one of a user's channels holding a concurrent stream slot,
+// either starting or live.
+type StreamSlot struct {
+	ChannelID string
+	Title     string
+	LiveState string
+}
+
+// StreamSlotReport reports a user's concurrent streams. Limit is the limit
+// in effect, zero when unlimited, and LimitOverride the admin override it
+// comes from, if any. Active lists the channels holding a slot.
+type StreamSlotReport struct {
+	UserID        string
+	Limit         int
+	LimitOverride *int
+	Active        []StreamSlot
+}
+
+// Kinds of ChannelStorageItem.
+const (
+	StorageItemRecording = "recording"
+	StorageItemUpload    = "upload"
+)
+
+// ChannelStorageItem is one recording or upload counted in a channel's
+// storage usage.
+type ChannelStorageItem struct {
+	Kind      string
+	ID        string
+	Title     string
+	SizeBytes int64
+	OverQuota bool
+	CreatedAt time.Time
 }
 
 // CreateUserParams captures the attributes that can be set when creating a user.
+//
+// Username is optional; without one the user gets a username derived from
+// their display name, which they may replace once without waiting for the
+// change cooldown.
 type CreateUserParams struct {
 	DisplayName string
+	Username    string
 	Email       string
 	Password    string
 	Roles       []string
 	SelfSignup  bool
 }
 
+// User listing sort keys accepted by UserListOptions.Sort.
+const (
+	UserSortCreatedAt   = "created_at"
+	UserSortDisplayName = "display_name"
+	UserSortEmail       = "email"
+)
+
+// UserListOptions filters, orders, and pages user and profile listings. Query
+// matches a case-insensitive substring of the email or display name, or of the
+// display name alone when NameOnly is set, Email keeps only the user with
+// that exact address (ignoring case), and Role keeps users holding that
+// role. Sort defaults to UserSortCreatedAt; ties are
+// broken by ID so pages stay stable. A non-positive Limit returns every match
+// after Offset.
+type UserListOptions struct {
+	Query      string
+	Email      string
+	NameOnly   bool
+	Role       string
+	Sort       string
+	Descending bool
+	Limit      int
+	Offset     int
+}
+
+// UserPage is one page of users along with the number of users matching the
+// filters across all pages.
+type UserPage struct {
+	Users []models.User
+	Total int
+}
+
+// ProfilePage is one page of profiles along with the number of profiles
+// matching the filters across all pages.
+type ProfilePage struct {
+	Profiles []models.Profile
+	Total    int
+}
+
+// FollowerListOptions pages a channel's followers. A non-positive Limit
+// returns every follower after Offset.
+type FollowerListOptions struct {
+	Limit  int
+	Offset int
+}
+
+// ChannelFollower summarizes a user who follows a channel.
+type ChannelFollower struct {
+	UserID      string
+	DisplayName string
+	AvatarURL   string
+	FollowedAt  time.Time
+}
+
+// FollowerPage is one page of followers, newest first, along with the number
+// of followers across all pages. Follows by users that no longer exist are
+// left out of both.
+type FollowerPage struct {
+	Followers []ChannelFollower
+	Total     int
+}
+
+// CreateAPITokenParams captures the attributes of a new personal access token.
+// Scopes default to read-only access when empty.
+type CreateAPITokenParams struct {
+	UserID    string
+	Name      string
+	Scopes    []string
+	ExpiresAt *time.Time
+}
+
 // OAuthLoginParams represents the identity information returned by an OAuth
 // provider used to authenticate or provision a user account.
 type OAuthLoginParams struct {
@@ -195,4 +716,37 @@ type CreateSubscriptionParams struct {
 	Duration          time.Duration
 	AutoRenew         bool
 	ExternalReference string
+	// GifterID identifies the viewer who paid for the subscription when
+	// IsGift is set.
+	GifterID string
+	IsGift   bool
+}
+
+// GiftSubscriptionsParams captures a batch of subscriptions purchased by one
+// viewer for other members of a channel. Each recipient receives a new
+// subscription referenced as "<Reference>-<n>", or has their active
+// subscription extended by Duration.
+type GiftSubscriptionsParams struct {
+	ChannelID    string
+	GifterID     string
+	RecipientIDs []string
+	Tier         string
+	Provider     string
+	Reference    string
+	Amount       models.Money
+	Currency     string
+	Duration     time.Duration
+}
+
+// SubscriptionStanding summarises a user's subscriptions to a channel for
+// chat. Active is set while the user holds an unexpired subscription, even
+// one cancelled before it ran out, and ExpiresAt is when the latest of them
+// does. Months is the cumulative time subscribed in whole months, summed
+// across gaps rather than reset by them.
+type SubscriptionStanding struct {
+	ChannelID string
+	UserID    string
+	Active    bool
+	Months    int
+	ExpiresAt *time.Time
 }
@@ -0,0 +1,145 @@
+package storage
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"bitriver-live/internal/models"
+)
+
+// ListUsersPage filters, orders, and pages users in memory.
+func (s *Storage) ListUsersPage(opts UserListOptions) (UserPage, error) {
+	less, err := userLess(opts)
+	if err != nil {
+		return UserPage{}, err
+	}
+	s.mu.RLock()
+	matches := make([]models.User, 0, len(s.data.Users))
+	for _, user := range s.data.Users {
+		if userMatchesListOptions(user, opts) {
+			matches = append(matches, user)
+		}
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool { return less(matches[i], matches[j]) })
+	start, end := pageBounds(len(matches), opts)
+	return UserPage{Users: matches[start:end], Total: len(matches)}, nil
+}
+
+// ListProfilesPage filters, orders, and pages profiles by their owning users.
+// Profiles whose user no longer exists are skipped.
+func (s *Storage) ListProfilesPage(opts UserListOptions) (ProfilePage, error) {
+	less, err := userLess(opts)
+	if err != nil {
+		return ProfilePage{}, err
+	}
+	type entry struct {
+		user    models.User
+		profile models.Profile
+	}
+	s.mu.RLock()
+	matches := make([]entry, 0, len(s.data.Profiles))
+	for userID, profile := range s.data.Profiles {
+		user, ok := s.data.Users[userID]
+		if !ok || !userMatchesListOptions(user, opts) {
+			continue
+		}
+		if profile.SocialLinks == nil {
+			profile.SocialLinks = []models.SocialLink{}
+		}
+		if profile.TopFriends == nil {
+			profile.TopFriends = []string{}
+		}
+		if profile.DonationAddresses == nil {
+			profile.DonationAddresses = []models.CryptoAddress{}
+		}
+		matches = append(matches, entry{user: user, profile: profile})
+	}
+	s.mu.RUnlock()
+
+	sort.Slice(matches, func(i, j int) bool { return less(matches[i].user, matches[j].user) })
+	start, end := pageBounds(len(matches), opts)
+	profiles := make([]models.Profile, 0, end-start)
+	for _, match := range matches[start:end] {
+		profiles = append(profiles, match.profile)
+	}
+	return ProfilePage{Profiles: profiles, Total: len(matches)}, nil
+}
+
+func userMatchesListOptions(user models.User, opts UserListOptions) bool {
+	if query := strings.ToLower(strings.TrimSpace(opts.Query)); query != "" {
+		nameMatch := strings.Contains(strings.ToLower(user.DisplayName), query)
+		emailMatch := !opts.NameOnly && strings.Contains(strings.ToLower(user.Email), query)
+		if !nameMatch && !emailMatch {
+			return false
+		}
+	}
+	if email := strings.TrimSpace(opts.Email); email != "" && !strings.EqualFold(user.Email, email) {
+		return false
+	}
+	if role := strings.TrimSpace(opts.Role); role != "" && !user.HasRole(role) {
+		return false
+	}
+	return true
+}
+
+// userLess orders users by the requested column, comparing names and emails
+// case-insensitively by byte value like the Postgres query, and falls back to
+// ID so equal values keep a stable order across pages.
+func userLess(opts UserListOptions) (func(a, b models.User) bool, error) {
+	if _, err := userSortColumn(opts.Sort, ""); err != nil {
+		return nil, err
+	}
+	compare := func(a, b models.User) int {
+		switch opts.Sort {
+		case UserSortDisplayName:
+			return strings.Compare(strings.ToLower(a.DisplayName), strings.ToLower(b.DisplayName))
+		case UserSortEmail:
+			return strings.Compare(strings.ToLower(a.Email), strings.ToLower(b.Email))
+		default:
+			return a.CreatedAt.Compare(b.CreatedAt)
+		}
+	}
+	return func(a, b models.User) bool {
+		c := compare(a, b)
+		if c == 0 {
+			c = strings.Compare(a.ID, b.ID)
+		}
+		if opts.Descending {
+			return c > 0
+		}
+		return c < 0
+	}, nil
+}
+
+// userSortColumn maps a sort key to the SQL expression it orders by, with
+// prefix qualifying the users table.
+func userSortColumn(key, prefix string) (string, error) {
+	switch key {
+	case "", UserSortCreatedAt:
+		return prefix + "created_at", nil
+	case UserSortDisplayName:
+		return fmt.Sprintf(`LOWER(%sdisplay_name) COLLATE "C"`, prefix), nil
+	case UserSortEmail:
+		return fmt.Sprintf(`LOWER(%semail) COLLATE "C"`, prefix), nil
+	default:
+		return "", fmt.Errorf("unsupported user sort %q", key)
+	}
+}
+
+func pageBounds(total int, opts UserListOptions) (int, int) {
+	start := opts.Offset
+	if start < 0 {
+		start = 0
+	}
+	if start > total {
+		start = total
+	}
+	end := total
+	if opts.Limit > 0 && start+opts.Limit < end {
+		end = start + opts.Limit
+	}
+	return start, end
+}
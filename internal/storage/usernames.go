@@ -0,0 +1,270 @@
+package storage
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"bitriver-live/internal/models"
+)
+
+// Username rules. Usernames are 3 to 25 letters, digits, or underscores and
+// start with a letter or digit.
+const (
+	MinUsernameLength = 3
+	MaxUsernameLength = 25
+)
+
+// UsernameChangeCooldown is how long a user waits between username changes.
+const UsernameChangeCooldown = 30 * 24 * time.Hour
+
+var (
+	// ErrUsernameInvalid is returned for usernames that break the length or
+	// character rules.
+	ErrUsernameInvalid = errors.New("username must be 3 to 25 letters, digits, or underscores and start with a letter or digit")
+	// ErrUsernameReserved is returned for usernames kept for the platform.
+	ErrUsernameReserved = errors.New("username is reserved")
+	// ErrUsernameTaken is returned when another user holds the username,
+	// ignoring case.
+	ErrUsernameTaken = conflict("username", "username is already taken")
+	// ErrUsernameCooldown is returned when the user changed their username
+	// less than UsernameChangeCooldown ago.
+	ErrUsernameCooldown = errors.New("username was changed too recently")
+)
+
+var usernamePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9_]*$`)
+
+// usernameSeparators matches the runs of characters derived usernames drop.
+// deploy/migrations/0044_usernames.sql applies the same rule in SQL.
+var usernameSeparators = regexp.MustCompile(`[^a-z0-9_]+`)
+
+// reservedUsernames would shadow routes, roles, or staff. The migration
+// keeps its own copy of this list.
+var reservedUsernames = map[string]struct{}{
+	"about":     {},
+	"admin":     {},
+	"api":       {},
+	"bitriver":  {},
+	"directory": {},
+	"embed":     {},
+	"help":      {},
+	"login":     {},
+	"logout":    {},
+	"me":        {},
+	"moderator": {},
+	"null":      {},
+	"root":      {},
+	"settings":  {},
+	"signup":    {},
+	"staff":     {},
+	"support":   {},
+	"system":    {},
+	"undefined": {},
+}
+
+// NormalizeUsername trims username and checks it against the length,
+// character, and reserved-word rules. It does not check uniqueness.
+func NormalizeUsername(username string) (string, error) {
+	username = strings.TrimSpace(username)
+	if len(username) < MinUsernameLength || len(username) > MaxUsernameLength || !usernamePattern.MatchString(username) {
+		return "", ErrUsernameInvalid
+	}
+	if usernameReserved(username) {
+		return "", fmt.Errorf("%w: %s", ErrUsernameReserved, username)
+	}
+	return username, nil
+}
+
+func usernameReserved(username string) bool {
+	_, reserved := reservedUsernames[usernameKey(username)]
+	return reserved
+}
+
+// usernameKey is the form usernames are compared in.
+func usernameKey(username string) string {
+	return strings.ToLower(strings.TrimSpace(username))
+}
+
+// usernameBase turns free text into a candidate username, or "" when too
+// little of it is usable.
+func usernameBase(text string) string {
+	base := strings.Trim(usernameSeparators.ReplaceAllString(strings.ToLower(text), "_"), "_")
+	if len(base) > MaxUsernameLength {
+		base = strings.TrimRight(base[:MaxUsernameLength], "_")
+	}
+	if len(base) < MinUsernameLength {
+		return ""
+	}
+	return base
+}
+
+// deriveUsername picks an unused username for an account that has none,
+// from its display name, else its email's local part, else "user". Taken or
+// reserved candidates get a numeric suffix, starting at 2.
+func deriveUsername(displayName, email string, taken func(key string) (bool, error)) (string, error) {
+	base := usernameBase(displayName)
+	if base == "" {
+		local, _, _ := strings.Cut(email, "@")
+		base = usernameBase(local)
+	}
+	if base == "" {
+		base = "user"
+	}
+	candidate := base
+	for suffix := 2; ; suffix++ {
+		if !usernameReserved(candidate) {
+			inUse, err := taken(candidate)
+			if err != nil {
+				return "", err
+			}
+			if !inUse {
+				return candidate, nil
+			}
+		}
+		tail := strconv.Itoa(suffix)
+		candidate = base[:min(len(base), MaxUsernameLength-len(tail))] + tail
+	}
+}
+
+// chooseUsername resolves the username of a new account: the one requested,
+// which counts as a change made at now, or a derived one with a nil change
+// time.
+func chooseUsername(params CreateUserParams, now time.Time, taken func(key string) (bool, error)) (string, *time.Time, error) {
+	if strings.TrimSpace(params.Username) == "" {
+		username, err := deriveUsername(params.DisplayName, params.Email, taken)
+		return username, nil, err
+	}
+	username, err := NormalizeUsername(params.Username)
+	if err != nil {
+		return "", nil, err
+	}
+	inUse, err := taken(usernameKey(username))
+	if err != nil {
+		return "", nil, err
+	}
+	if inUse {
+		return "", nil, ErrUsernameTaken
+	}
+	changedAt := now.UTC()
+	return username, &changedAt, nil
+}
+
+// checkUsernameCooldown reports whether user may change their username at
+// now.
+func checkUsernameCooldown(user models.User, now time.Time) error {
+	if user.UsernameGrace || user.UsernameChangedAt == nil {
+		return nil
+	}
+	if next := user.UsernameChangedAt.Add(UsernameChangeCooldown); now.Before(next) {
+		return fmt.Errorf("%w; it can be changed again after %s", ErrUsernameCooldown, next.UTC().Format(time.RFC3339))
+	}
+	return nil
+}
+
+// backfillUsernames assigns derived usernames, marked for a free change, to
+// users stored before usernames existed, oldest account first. It reports
+// whether any user was changed.
+func backfillUsernames(users map[string]models.User) bool {
+	used := make(map[string]struct{}, len(users))
+	var missing []string
+	for key, user := range users {
+		if user.Username == "" {
+			missing = append(missing, key)
+			continue
+		}
+		used[usernameKey(user.Username)] = struct{}{}
+	}
+	if len(missing) == 0 {
+		return false
+	}
+	sort.Slice(missing, func(i, j int) bool {
+		a, b := users[missing[i]], users[missing[j]]
+		if !a.CreatedAt.Equal(b.CreatedAt) {
+			return a.CreatedAt.Before(b.CreatedAt)
+		}
+		return missing[i] < missing[j]
+	})
+	for _, key := range missing {
+		user := users[key]
+		username, _ := deriveUsername(user.DisplayName, user.Email, func(candidate string) (bool, error) {
+			_, inUse := used[candidate]
+			return inUse, nil
+		})
+		used[username] = struct{}{}
+		user.Username = username
+		user.UsernameGrace = true
+		users[key] = user
+	}
+	return true
+}
+
+// usernameTaken reports whether a user other than exceptID holds username,
+// ignoring case.
+func usernameTaken(users map[string]models.User, username, exceptID string) bool {
+	key := usernameKey(username)
+	for id, user := range users {
+		if id != exceptID && usernameKey(user.Username) == key {
+			return true
+		}
+	}
+	return false
+}
+
+// FindUserByUsername looks up a user by username, ignoring case.
+func (s *Storage) FindUserByUsername(username string) (models.User, bool) {
+	key := usernameKey(username)
+	if key == "" {
+		return models.User{}, false
+	}
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	for _, user := range s.data.Users {
+		if usernameKey(user.Username) == key {
+			return user, true
+		}
+	}
+	return models.User{}, false
+}
+
+// ChangeUsername sets the user's username. Outside the grace given to
+// assigned usernames, it can change once per UsernameChangeCooldown;
+// setting the current username again is a no-op.
+func (s *Storage) ChangeUsername(id, username string, now time.Time) (models.User, error) {
+	username, err := NormalizeUsername(username)
+	if err != nil {
+		return models.User{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	user, ok := s.data.Users[id]
+	if !ok {
+		return models.User{}, notFound("user", id)
+	}
+	if user.Username == username {
+		return user, nil
+	}
+	if err := checkUsernameCooldown(user, now); err != nil {
+		return models.User{}, err
+	}
+	if usernameTaken(s.data.Users, username, id) {
+		return models.User{}, ErrUsernameTaken
+	}
+
+	previous := user
+	changedAt := now.UTC()
+	user.Username = username
+	user.UsernameChangedAt = &changedAt
+	user.UsernameGrace = false
+	s.data.Users[id] = user
+	if err := s.persist(); err != nil {
+		s.data.Users[id] = previous
+		return models.User{}, err
+	}
+	return user, nil
+}
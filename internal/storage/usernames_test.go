@@ -0,0 +1,74 @@
+package storage
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestStorageBackfillsUsernamesOnLoad(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "store.json")
+	legacy := `{
+  "users": {
+    "u1": {"id": "u1", "displayName": "Ada Lovelace", "email": "ada@example.com", "createdAt": "2024-01-01T00:00:00Z"},
+    "u2": {"id": "u2", "displayName": "ada lovelace!", "email": "ada2@example.com", "createdAt": "2024-01-02T00:00:00Z"},
+    "u3": {"id": "u3", "displayName": "Admin", "email": "root@example.com", "createdAt": "2024-01-03T00:00:00Z"},
+    "u4": {"id": "u4", "displayName": "李", "email": "grace.hopper@example.com", "createdAt": "2024-01-04T00:00:00Z"},
+    "u5": {"id": "u5", "displayName": "☃", "email": "x@example.com", "createdAt": "2024-01-05T00:00:00Z"},
+    "u6": {"id": "u6", "displayName": "Kept", "username": "Ada_Lovelace2", "email": "kept@example.com", "createdAt": "2024-01-06T00:00:00Z"}
+  }
+}`
+	if err := os.WriteFile(path, []byte(legacy), 0o600); err != nil {
+		t.Fatalf("WriteFile: %v", err)
+	}
+
+	store, err := NewStorage(path)
+	if err != nil {
+		t.Fatalf("NewStorage: %v", err)
+	}
+	want := map[string]string{
+		"u1": "ada_lovelace",
+		// ada_lovelace2 is held by u6 ignoring case, so the suffix moves on.
+		"u2": "ada_lovelace3",
+		"u3": "admin2",
+		"u4": "grace_hopper",
+		"u5": "user",
+		"u6": "Ada_Lovelace2",
+	}
+	for id, username := range want {
+		user, ok := store.GetUser(id)
+		if !ok {
+			t.Fatalf("expected user %s to load", id)
+		}
+		if user.Username != username {
+			t.Errorf("user %s: expected username %q, got %q", id, username, user.Username)
+		}
+		if grace := id != "u6"; user.UsernameGrace != grace || user.UsernameChangedAt != nil {
+			t.Errorf("user %s: expected grace %v and no change time, got %+v", id, grace, user)
+		}
+	}
+
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		t.Fatalf("ReadFile: %v", err)
+	}
+	if !strings.Contains(string(raw), `"username": "ada_lovelace3"`) {
+		t.Fatal("expected the backfilled usernames to be persisted")
+	}
+}
+
+func TestDeriveUsernameKeepsSuffixWithinLimit(t *testing.T) {
+	long := strings.Repeat("a", 40)
+	taken := map[string]bool{strings.Repeat("a", 25): true}
+	username, err := deriveUsername(long, "", func(key string) (bool, error) { return taken[key], nil })
+	if err != nil {
+		t.Fatalf("deriveUsername: %v", err)
+	}
+	if want := strings.Repeat("a", 24) + "2"; username != want {
+		t.Fatalf("expected %q, got %q", want, username)
+	}
+	if _, err := NormalizeUsername(username); err != nil {
+		t.Fatalf("expected the derived username to be valid: %v", err)
+	}
+}
@@ -9,11 +9,15 @@ import (
 	"strings"
 	"time"
 
+	"bitriver-live/internal/ingest"
 	"bitriver-live/internal/models"
 )
 
 func cloneRecording(recording models.Recording) models.Recording {
 	cloned := recording
+	if recording.Tags != nil {
+		cloned.Tags = append([]string(nil), recording.Tags...)
+	}
 	if recording.Renditions != nil {
 		cloned.Renditions = append([]models.RecordingRendition(nil), recording.Renditions...)
 	}
@@ -35,9 +39,16 @@ func cloneRecording(recording models.Recording) models.Recording {
 		retain := *recording.RetainUntil
 		cloned.RetainUntil = &retain
 	}
+	if recording.PremiereAt != nil {
+		premiere := *recording.PremiereAt
+		cloned.PremiereAt = &premiere
+	}
 	if recording.Clips != nil {
 		cloned.Clips = append([]models.ClipExportSummary(nil), recording.Clips...)
 	}
+	if recording.Chapters != nil {
+		cloned.Chapters = append([]models.RecordingChapter(nil), recording.Chapters...)
+	}
 	return cloned
 }
 
@@ -58,9 +69,64 @@ func cloneUpload(upload models.Upload) models.Upload {
 		completed := *upload.CompletedAt
 		cloned.CompletedAt = &completed
 	}
+	cloned.Steps = cloneUploadSteps(upload.Steps)
+	return cloned
+}
+
+func cloneUploadSteps(steps []models.UploadStep) []models.UploadStep {
+	if steps == nil {
+		return nil
+	}
+	cloned := make([]models.UploadStep, len(steps))
+	for i, step := range steps {
+		if step.StartedAt != nil {
+			started := *step.StartedAt
+			step.StartedAt = &started
+		}
+		if step.FinishedAt != nil {
+			finished := *step.FinishedAt
+			step.FinishedAt = &finished
+		}
+		cloned[i] = step
+	}
 	return cloned
 }
 
+// normalizeUploadStep validates step and returns it with its times in UTC.
+func normalizeUploadStep(step models.UploadStep) (models.UploadStep, error) {
+	step.Name = strings.TrimSpace(step.Name)
+	step.Status = strings.TrimSpace(step.Status)
+	step.Detail = strings.TrimSpace(step.Detail)
+	if !models.ValidUploadStep(step) {
+		return models.UploadStep{}, fmt.Errorf("unknown upload step %q in state %q", step.Name, step.Status)
+	}
+	if step.StartedAt != nil {
+		started := step.StartedAt.UTC()
+		step.StartedAt = &started
+	}
+	if step.FinishedAt != nil {
+		finished := step.FinishedAt.UTC()
+		step.FinishedAt = &finished
+	}
+	return step, nil
+}
+
+// applyUploadSteps replaces the upload's steps with steps and derives its
+// progress from them.
+func applyUploadSteps(upload *models.Upload, steps []models.UploadStep) error {
+	normalized := make([]models.UploadStep, 0, len(steps))
+	for _, step := range steps {
+		step, err := normalizeUploadStep(step)
+		if err != nil {
+			return err
+		}
+		normalized = append(normalized, step)
+	}
+	upload.Steps = normalized
+	upload.Progress = models.UploadProgress(normalized)
+	return nil
+}
+
 func cloneClipExport(clip models.ClipExport) models.ClipExport {
 	cloned := clip
 	if clip.CompletedAt != nil {
@@ -91,6 +157,7 @@ func (s *Storage) purgeExpiredRecordingsLocked(now time.Time) (bool, dataset, er
 	removed := false
 	snapshotTaken := false
 	var snapshot dataset
+	freed := make(map[string]struct{})
 	for id, recording := range s.data.Recordings {
 		if recording.RetainUntil == nil || now.Before(*recording.RetainUntil) {
 			continue
@@ -118,11 +185,16 @@ func (s *Storage) purgeExpiredRecordingsLocked(now time.Time) (bool, dataset, er
 			delete(s.data.ClipExports, clipID)
 		}
 		delete(s.data.Recordings, id)
+		s.adjustChannelStorageLocked(recording.ChannelID, -recording.SizeBytes, now)
+		freed[recording.ChannelID] = struct{}{}
 		removed = true
 	}
 	if !removed {
 		return false, dataset{}, nil
 	}
+	for channelID := range freed {
+		s.releaseOverQuotaRecordingsLocked(channelID, now)
+	}
 	return true, snapshot, nil
 }
 
@@ -133,7 +205,10 @@ func (s *Storage) retentionTime() time.Time {
 	return time.Now().UTC()
 }
 
-func (s *Storage) runRecordingRetention(_ context.Context) error {
+// PurgeExpiredRecordings deletes recordings, and their clips, whose
+// retention window has passed. Listing recordings purges lazily as well, so
+// this only matters for channels nobody is browsing.
+func (s *Storage) PurgeExpiredRecordings(_ context.Context) error {
 	now := s.retentionTime()
 
 	s.mu.Lock()
@@ -184,7 +259,11 @@ func (s *Storage) recordingWithClipsLocked(recording models.Recording) models.Re
 	return cloned
 }
 
-func (s *Storage) createRecordingLocked(session models.StreamSession, channel models.Channel, ended time.Time) (models.Recording, error) {
+// createRecordingLocked builds the recording of an ended session.
+// outputBytes is what the transcoder reported writing; artifacts uploaded to
+// object storage are added on top. A recording that does not fit in the
+// channel's storage quota is still kept, but flagged and left unpublished.
+func (s *Storage) createRecordingLocked(session models.StreamSession, channel models.Channel, ended time.Time, frame ingest.Frame, outputBytes int64) (models.Recording, error) {
 	s.ensureDatasetInitializedLocked()
 	id, err := generateID()
 	if err != nil {
@@ -209,6 +288,7 @@ func (s *Storage) createRecordingLocked(session models.StreamSession, channel mo
 	if session.PeakConcurrent > 0 {
 		metadata["peakConcurrent"] = strconv.Itoa(session.PeakConcurrent)
 	}
+	addStreamSettingsMetadata(metadata, session.Settings)
 	recording := models.Recording{
 		ID:              id,
 		ChannelID:       channel.ID,
@@ -218,8 +298,14 @@ func (s *Storage) createRecordingLocked(session models.StreamSession, channel mo
 		PlaybackBaseURL: session.PlaybackURL,
 		Metadata:        metadata,
 		CreatedAt:       ended,
+		SizeBytes:       outputBytes,
 	}
-	if deadline := s.recordingDeadline(ended, false); deadline != nil {
+	published := channel.RecordingPolicy == models.RecordingPolicyAutoPublish
+	if published {
+		publishedAt := ended
+		recording.PublishedAt = &publishedAt
+	}
+	if deadline := s.recordingDeadline(ended, published); deadline != nil {
 		recording.RetainUntil = deadline
 	}
 	if len(session.RenditionManifests) > 0 {
@@ -229,13 +315,19 @@ func (s *Storage) createRecordingLocked(session models.StreamSession, channel mo
 		}
 		recording.Renditions = renditions
 	}
-	if err := s.populateRecordingArtifactsLocked(&recording, session); err != nil {
+	recording.Chapters = chaptersFromMarkers(s.sessionMarkersLocked(session.ID), duration)
+	if err := s.populateRecordingArtifactsLocked(&recording, session, frame); err != nil {
 		return models.Recording{}, err
 	}
+	if usage, quota := s.channelStorageLocked(channel.ID); overStorageQuota(usage.UsedBytes+recording.SizeBytes, quota) {
+		recording.OverQuota = true
+		recording.PublishedAt = nil
+		recording.RetainUntil = s.recordingDeadline(ended, false)
+	}
 	return recording, nil
 }
 
-func (s *Storage) populateRecordingArtifactsLocked(recording *models.Recording, session models.StreamSession) error {
+func (s *Storage) populateRecordingArtifactsLocked(recording *models.Recording, session models.StreamSession, frame ingest.Frame) error {
 	client := s.objectClient
 	if client == nil || !client.Enabled() {
 		return nil
@@ -267,6 +359,7 @@ func (s *Storage) populateRecordingArtifactsLocked(recording *models.Recording,
 			if err != nil {
 				return fmt.Errorf("upload manifest %s: %w", manifest.Name, err)
 			}
+			recording.SizeBytes += int64(len(data))
 			if ref.Key != "" {
 				recording.Metadata[manifestMetadataKey(manifest.Name)] = ref.Key
 			}
@@ -275,38 +368,7 @@ func (s *Storage) populateRecordingArtifactsLocked(recording *models.Recording,
 			}
 		}
 	}
-	thumbID, err := generateID()
-	if err != nil {
-		return fmt.Errorf("generate thumbnail id: %w", err)
-	}
-	thumbKey := buildObjectKey("recordings", recording.ID, "thumbnails", thumbID+".json")
-	thumbPayload := map[string]any{
-		"recordingId": recording.ID,
-		"sessionId":   recording.SessionID,
-		"createdAt":   createdAt,
-	}
-	thumbData, err := json.Marshal(thumbPayload)
-	if err != nil {
-		return fmt.Errorf("encode thumbnail payload: %w", err)
-	}
-	ctx, cancel := context.WithTimeout(context.Background(), s.objectStorage.requestTimeout())
-	ref, err := client.Upload(ctx, thumbKey, "application/json", thumbData)
-	cancel()
-	if err != nil {
-		return fmt.Errorf("upload thumbnail: %w", err)
-	}
-	if ref.Key != "" {
-		recording.Metadata[thumbnailMetadataKey(thumbID)] = ref.Key
-	}
-	thumbnail := models.RecordingThumbnail{
-		ID:          thumbID,
-		RecordingID: recording.ID,
-		URL:         ref.URL,
-		CreatedAt:   recording.CreatedAt,
-	}
-	recording.Thumbnails = append(recording.Thumbnails, thumbnail)
-
-	return nil
+	return attachRecordingThumbnail(client, s.objectStorage, recording, frame)
 }
 
 func (s *Storage) deleteRecordingArtifactsLocked(recording models.Recording) error {
@@ -359,7 +421,7 @@ func (s *Storage) ListRecordings(channelID string, includeUnpublished bool) ([]m
 	defer s.mu.Unlock()
 
 	if _, ok := s.data.Channels[channelID]; !ok {
-		return nil, fmt.Errorf("channel %s not found", channelID)
+		return nil, notFound("channel", channelID)
 	}
 
 	now := s.retentionTime()
@@ -385,7 +447,10 @@ func (s *Storage) ListRecordings(channelID string, includeUnpublished bool) ([]m
 		recordings = append(recordings, s.recordingWithClipsLocked(recording))
 	}
 	sort.Slice(recordings, func(i, j int) bool {
-		return recordings[i].CreatedAt.After(recordings[j].CreatedAt)
+		if !recordings[i].CreatedAt.Equal(recordings[j].CreatedAt) {
+			return recordings[i].CreatedAt.After(recordings[j].CreatedAt)
+		}
+		return recordings[i].ID < recordings[j].ID
 	})
 	return recordings, nil
 }
@@ -399,7 +464,7 @@ func (s *Storage) CreateUpload(params CreateUploadParams) (models.Upload, error)
 	channelID := strings.TrimSpace(params.ChannelID)
 	channel, ok := s.data.Channels[channelID]
 	if !ok {
-		return models.Upload{}, fmt.Errorf("channel %s not found", channelID)
+		return models.Upload{}, notFound("channel", channelID)
 	}
 
 	title := strings.TrimSpace(params.Title)
@@ -432,12 +497,17 @@ func (s *Storage) CreateUpload(params CreateUploadParams) (models.Upload, error)
 
 	playbackURL := strings.TrimSpace(params.PlaybackURL)
 
+	if usage, quota := s.channelStorageLocked(channelID); exceedsStorageQuota(usage.UsedBytes, params.SizeBytes, quota) {
+		return models.Upload{}, storageQuotaError(usage.UsedBytes, params.SizeBytes, quota)
+	}
+
 	upload := models.Upload{
 		ID:          id,
 		ChannelID:   channelID,
 		Title:       title,
 		Filename:    filename,
 		SizeBytes:   params.SizeBytes,
+		ContentHash: strings.ToLower(strings.TrimSpace(params.ContentHash)),
 		Status:      "pending",
 		Progress:    0,
 		Metadata:    metadata,
@@ -446,9 +516,11 @@ func (s *Storage) CreateUpload(params CreateUploadParams) (models.Upload, error)
 		UpdatedAt:   now,
 	}
 
+	snapshot := cloneDataset(s.data)
 	s.data.Uploads[id] = upload
+	s.adjustChannelStorageLocked(channelID, upload.StorageBytes(), now)
 	if err := s.persist(); err != nil {
-		delete(s.data.Uploads, id)
+		s.data = snapshot
 		return models.Upload{}, err
 	}
 
@@ -460,7 +532,7 @@ func (s *Storage) ListUploads(channelID string) ([]models.Upload, error) {
 	defer s.mu.RUnlock()
 
 	if _, ok := s.data.Channels[channelID]; !ok {
-		return nil, fmt.Errorf("channel %s not found", channelID)
+		return nil, notFound("channel", channelID)
 	}
 
 	uploads := make([]models.Upload, 0)
@@ -471,7 +543,10 @@ func (s *Storage) ListUploads(channelID string) ([]models.Upload, error) {
 		uploads = append(uploads, cloneUpload(upload))
 	}
 	sort.Slice(uploads, func(i, j int) bool {
-		return uploads[i].CreatedAt.After(uploads[j].CreatedAt)
+		if !uploads[i].CreatedAt.Equal(uploads[j].CreatedAt) {
+			return uploads[i].CreatedAt.After(uploads[j].CreatedAt)
+		}
+		return uploads[i].ID < uploads[j].ID
 	})
 	return uploads, nil
 }
@@ -493,11 +568,17 @@ func (s *Storage) UpdateUpload(id string, update UploadUpdate) (models.Upload, e
 
 	upload, ok := s.data.Uploads[id]
 	if !ok {
-		return models.Upload{}, fmt.Errorf("upload %s not found", id)
+		return models.Upload{}, notFound("upload", id)
 	}
 
 	original := upload
+	upload.Steps = cloneUploadSteps(upload.Steps)
 
+	if update.Steps != nil {
+		if err := applyUploadSteps(&upload, update.Steps); err != nil {
+			return models.Upload{}, err
+		}
+	}
 	if update.Title != nil {
 		if trimmed := strings.TrimSpace(*update.Title); trimmed != "" {
 			upload.Title = trimmed
@@ -554,33 +635,106 @@ func (s *Storage) UpdateUpload(id string, update UploadUpdate) (models.Upload, e
 		}
 	}
 
+	if update.OutputBytes != nil {
+		if *update.OutputBytes < 0 {
+			return models.Upload{}, fmt.Errorf("outputBytes must be zero or greater")
+		}
+		upload.OutputBytes = *update.OutputBytes
+	}
+
 	upload.UpdatedAt = time.Now().UTC()
 
+	snapshot := cloneDataset(s.data)
 	s.data.Uploads[id] = upload
+	if delta := upload.StorageBytes() - original.StorageBytes(); delta != 0 {
+		s.adjustChannelStorageLocked(upload.ChannelID, delta, upload.UpdatedAt)
+		if delta < 0 {
+			s.releaseOverQuotaRecordingsLocked(upload.ChannelID, upload.UpdatedAt)
+		}
+	}
 	if err := s.persist(); err != nil {
-		s.data.Uploads[id] = original
+		s.data = snapshot
 		return models.Upload{}, err
 	}
 	return cloneUpload(upload), nil
 }
 
+func (s *Storage) UpdateUploadStep(id string, step models.UploadStep) error {
+	step, err := normalizeUploadStep(step)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	upload, ok := s.data.Uploads[id]
+	if !ok {
+		return notFound("upload", id)
+	}
+	upload = cloneUpload(upload)
+	upload.ApplyStep(step)
+	upload.UpdatedAt = time.Now().UTC()
+
+	snapshot := cloneDataset(s.data)
+	s.data.Uploads[id] = upload
+	if err := s.persist(); err != nil {
+		s.data = snapshot
+		return err
+	}
+	return nil
+}
+
 func (s *Storage) DeleteUpload(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
 	upload, ok := s.data.Uploads[id]
 	if !ok {
-		return fmt.Errorf("upload %s not found", id)
+		return notFound("upload", id)
 	}
 
+	snapshot := cloneDataset(s.data)
+	now := time.Now().UTC()
 	delete(s.data.Uploads, id)
+	s.adjustChannelStorageLocked(upload.ChannelID, -upload.StorageBytes(), now)
+	s.releaseOverQuotaRecordingsLocked(upload.ChannelID, now)
 	if err := s.persist(); err != nil {
-		s.data.Uploads[id] = upload
+		s.data = snapshot
 		return err
 	}
 	return nil
 }
 
+func (s *Storage) FindReadyUploadByContentHash(channelID, contentHash string) (models.Upload, bool) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	channelID = strings.TrimSpace(channelID)
+	contentHash = strings.ToLower(strings.TrimSpace(contentHash))
+	if channelID == "" || contentHash == "" {
+		return models.Upload{}, false
+	}
+
+	var (
+		match models.Upload
+		found bool
+	)
+	for _, upload := range s.data.Uploads {
+		if upload.ChannelID != channelID || upload.ContentHash != contentHash || upload.Status != "ready" {
+			continue
+		}
+		if !found || upload.CreatedAt.After(match.CreatedAt) || (upload.CreatedAt.Equal(match.CreatedAt) && upload.ID < match.ID) {
+			match = upload
+			found = true
+		}
+	}
+	if !found {
+		return models.Upload{}, false
+	}
+	return cloneUpload(match), true
+}
+
 func (s *Storage) GetRecording(id string) (models.Recording, bool) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -616,14 +770,25 @@ func (s *Storage) PublishRecording(id string) (models.Recording, error) {
 
 	recording, ok := s.data.Recordings[id]
 	if !ok {
-		return models.Recording{}, fmt.Errorf("recording %s not found", id)
+		return models.Recording{}, notFound("recording", id)
 	}
 	if recording.PublishedAt != nil {
 		return s.recordingWithClipsLocked(recording), nil
 	}
-
 	now := time.Now().UTC()
+	if recording.PremiereAt != nil && recording.PremiereAt.After(now) {
+		return models.Recording{}, ErrRecordingPremiereScheduled
+	}
+	if recording.OverQuota {
+		// The flag is normally cleared as soon as space is freed, but the
+		// platform quota may have been raised since.
+		if usage, quota := s.channelStorageLocked(recording.ChannelID); overStorageQuota(usage.UsedBytes, quota) {
+			return models.Recording{}, storageQuotaError(usage.UsedBytes, 0, quota)
+		}
+	}
+
 	updated := cloneRecording(recording)
+	updated.OverQuota = false
 	updated.PublishedAt = &now
 	if deadline := s.recordingDeadline(now, true); deadline != nil {
 		updated.RetainUntil = deadline
@@ -640,6 +805,36 @@ func (s *Storage) PublishRecording(id string) (models.Recording, error) {
 	return s.recordingWithClipsLocked(updated), nil
 }
 
+// UnpublishRecording hides a published recording from viewers. Its retention
+// falls back to the unpublished window from now.
+func (s *Storage) UnpublishRecording(id string) (models.Recording, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if id == "" {
+		return models.Recording{}, fmt.Errorf("recording id is required")
+	}
+	recording, ok := s.data.Recordings[id]
+	if !ok {
+		return models.Recording{}, notFound("recording", id)
+	}
+	if recording.PublishedAt == nil {
+		return s.recordingWithClipsLocked(recording), nil
+	}
+
+	updated := cloneRecording(recording)
+	updated.PublishedAt = nil
+	updated.RetainUntil = s.recordingDeadline(time.Now().UTC(), false)
+
+	snapshot := cloneDataset(s.data)
+	s.data.Recordings[id] = updated
+	if err := s.persist(); err != nil {
+		s.data = snapshot
+		return models.Recording{}, err
+	}
+	return s.recordingWithClipsLocked(updated), nil
+}
+
 func (s *Storage) DeleteRecording(id string) error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -649,7 +844,7 @@ func (s *Storage) DeleteRecording(id string) error {
 	}
 	recording, ok := s.data.Recordings[id]
 	if !ok {
-		return fmt.Errorf("recording %s not found", id)
+		return notFound("recording", id)
 	}
 	if err := s.deleteRecordingArtifactsLocked(recording); err != nil {
 		return err
@@ -665,7 +860,10 @@ func (s *Storage) DeleteRecording(id string) error {
 		}
 		delete(s.data.ClipExports, clipID)
 	}
+	now := time.Now().UTC()
 	delete(s.data.Recordings, id)
+	s.adjustChannelStorageLocked(recording.ChannelID, -recording.SizeBytes, now)
+	s.releaseOverQuotaRecordingsLocked(recording.ChannelID, now)
 	if err := s.persist(); err != nil {
 		s.data = snapshot
 		return err
@@ -682,11 +880,11 @@ func (s *Storage) CreateClipExport(recordingID string, params ClipExportParams)
 	}
 	recording, ok := s.data.Recordings[recordingID]
 	if !ok {
-		return models.ClipExport{}, fmt.Errorf("recording %s not found", recordingID)
+		return models.ClipExport{}, notFound("recording", recordingID)
 	}
 	title := strings.TrimSpace(params.Title)
 	if title == "" {
-		return models.ClipExport{}, fmt.Errorf("title is required")
+		return models.ClipExport{}, invalid("title", "title is required")
 	}
 	if params.EndSeconds <= params.StartSeconds {
 		return models.ClipExport{}, fmt.Errorf("endSeconds must be greater than startSeconds")
@@ -730,7 +928,7 @@ func (s *Storage) ListClipExports(recordingID string) ([]models.ClipExport, erro
 		return nil, fmt.Errorf("recording id is required")
 	}
 	if _, ok := s.data.Recordings[recordingID]; !ok {
-		return nil, fmt.Errorf("recording %s not found", recordingID)
+		return nil, notFound("recording", recordingID)
 	}
 	clips := make([]models.ClipExport, 0)
 	for _, clip := range s.data.ClipExports {
@@ -740,7 +938,10 @@ func (s *Storage) ListClipExports(recordingID string) ([]models.ClipExport, erro
 		clips = append(clips, cloneClipExport(clip))
 	}
 	sort.Slice(clips, func(i, j int) bool {
-		return clips[i].CreatedAt.After(clips[j].CreatedAt)
+		if !clips[i].CreatedAt.Equal(clips[j].CreatedAt) {
+			return clips[i].CreatedAt.After(clips[j].CreatedAt)
+		}
+		return clips[i].ID < clips[j].ID
 	})
 	return clips, nil
 }
@@ -1,8 +1,11 @@
 package storage
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"image"
+	"image/jpeg"
 	"net/http"
 	"net/http/httptest"
 	"strings"
@@ -20,7 +23,9 @@ func TestStopStreamUploadsRecordingArtifacts(t *testing.T) {
 			{Name: "1080p", ManifestURL: "https://origin/1080p.m3u8", Bitrate: 6000},
 			{Name: "720p", ManifestURL: "https://origin/720p.m3u8", Bitrate: 3500},
 		},
+		JobIDs: []string{"job-1"},
 	}}}}
+	controller.frame = ingest.Frame{Data: testJPEG(t, 320, 180), ContentType: "image/jpeg"}
 	objectCfg := WithObjectStorage(ObjectStorageConfig{
 		Bucket:         "vod",
 		Prefix:         "vod/assets",
@@ -82,6 +87,69 @@ func TestStopStreamUploadsRecordingArtifacts(t *testing.T) {
 	if recording.Thumbnails[0].URL != fakeStorage.uploads[2].URL {
 		t.Fatalf("expected thumbnail URL to reference uploaded object")
 	}
+	thumb := fakeStorage.uploads[2]
+	if thumb.ContentType != "image/jpeg" || !strings.HasSuffix(thumb.Key, ".jpg") {
+		t.Fatalf("expected JPEG thumbnail upload, got %s %s", thumb.ContentType, thumb.Key)
+	}
+	if !bytes.Equal(thumb.Body, controller.frame.Data) {
+		t.Fatalf("expected thumbnail upload to carry the captured frame")
+	}
+	if recording.Thumbnails[0].Width != 320 || recording.Thumbnails[0].Height != 180 {
+		t.Fatalf("expected 320x180 thumbnail, got %dx%d", recording.Thumbnails[0].Width, recording.Thumbnails[0].Height)
+	}
+	if len(controller.frameJobIDs) != 1 || controller.frameJobIDs[0] != "job-1" {
+		t.Fatalf("expected frame capture from job-1, got %v", controller.frameJobIDs)
+	}
+}
+
+func TestStopStreamWithoutFrameSkipsThumbnail(t *testing.T) {
+	controller := &fakeIngestController{bootResponses: []bootResponse{{result: ingest.BootResult{
+		Renditions: []ingest.Rendition{{Name: "720p", ManifestURL: "https://origin/720p.m3u8"}},
+		JobIDs:     []string{"job-1"},
+	}}}}
+	store := newTestStoreWithController(t, controller, WithObjectStorage(ObjectStorageConfig{
+		Bucket:         "vod",
+		PublicEndpoint: "https://cdn.example.com/content",
+	}))
+	fakeStorage := &fakeObjectStorage{baseURL: store.objectStorage.PublicEndpoint}
+	store.objectClient = fakeStorage
+
+	owner, err := store.CreateUser(CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Live", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	if _, err := store.StartStream(channel.ID, []string{"720p"}); err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	if _, err := store.StopStream(channel.ID, 0); err != nil {
+		t.Fatalf("StopStream: %v", err)
+	}
+
+	for _, upload := range fakeStorage.uploads {
+		if strings.Contains(upload.Key, "/thumbnails/") {
+			t.Fatalf("expected no thumbnail upload without a frame, got %s", upload.Key)
+		}
+	}
+	recordings, err := store.ListRecordings(channel.ID, true)
+	if err != nil || len(recordings) != 1 {
+		t.Fatalf("ListRecordings: %v (count %d)", err, len(recordings))
+	}
+	if len(recordings[0].Thumbnails) != 0 {
+		t.Fatalf("expected no thumbnails, got %+v", recordings[0].Thumbnails)
+	}
+}
+
+func testJPEG(t *testing.T, width, height int) []byte {
+	t.Helper()
+	var buf bytes.Buffer
+	if err := jpeg.Encode(&buf, image.NewGray(image.Rect(0, 0, width, height)), nil); err != nil {
+		t.Fatalf("encode jpeg: %v", err)
+	}
+	return buf.Bytes()
 }
 
 func TestPopulateRecordingArtifactsTimeout(t *testing.T) {
@@ -121,7 +189,7 @@ func TestPopulateRecordingArtifactsTimeout(t *testing.T) {
 		}},
 	}
 
-	err := store.populateRecordingArtifactsLocked(&recording, session)
+	err := store.populateRecordingArtifactsLocked(&recording, session, ingest.Frame{})
 	if err == nil {
 		t.Fatalf("expected populateRecordingArtifactsLocked to fail when request blocks")
 	}
@@ -372,10 +440,22 @@ func TestRepositoryStreamTimeouts(t *testing.T) {
 	RunRepositoryStreamTimeouts(t, jsonRepositoryFactory)
 }
 
+func TestRepositoryStreamSettingsSnapshot(t *testing.T) {
+	RunRepositoryStreamSettingsSnapshot(t, jsonRepositoryFactory)
+}
+
+func TestRepositoryStreamPlaybackEndpoints(t *testing.T) {
+	RunRepositoryStreamPlaybackEndpoints(t, jsonRepositoryFactory)
+}
+
 func TestRecordingRetentionPurgesExpired(t *testing.T) {
 	RunRepositoryRecordingRetention(t, jsonRepositoryFactory)
 }
 
+func TestRecordingPolicy(t *testing.T) {
+	RunRepositoryRecordingPolicy(t, jsonRepositoryFactory)
+}
+
 func TestRecordingRetentionDeleteFailures(t *testing.T) {
 	RunRepositoryRecordingRetentionFailures(t, jsonRepositoryFactory)
 }
@@ -383,3 +463,48 @@ func TestRecordingRetentionDeleteFailures(t *testing.T) {
 func TestClipExportTitleValidation(t *testing.T) {
 	RunRepositoryClipExportTitleValidation(t, jsonRepositoryFactory)
 }
+
+func TestRecordingChaptersStayWithinDuration(t *testing.T) {
+	store := newTestStore(t)
+	owner, err := store.CreateUser(CreateUserParams{DisplayName: "Owner", Email: "owner@example.com", Roles: []string{"creator"}})
+	if err != nil {
+		t.Fatalf("CreateUser: %v", err)
+	}
+	channel, err := store.CreateChannel(owner.ID, "Live", "gaming", nil)
+	if err != nil {
+		t.Fatalf("CreateChannel: %v", err)
+	}
+	session, err := store.StartStream(channel.ID, []string{"720p"})
+	if err != nil {
+		t.Fatalf("StartStream: %v", err)
+	}
+	// Backdate the session so the recording lasts about a minute.
+	store.mu.Lock()
+	session.StartedAt = time.Now().UTC().Add(-time.Minute)
+	store.data.StreamSessions[session.ID] = session
+	store.mu.Unlock()
+	for _, offset := range []time.Duration{30 * time.Second, 5 * time.Minute} {
+		if _, err := store.CreateStreamMarker(channel.ID, StreamMarkerParams{UserID: owner.ID, Label: "Marker", At: session.StartedAt.Add(offset)}); err != nil {
+			t.Fatalf("CreateStreamMarker: %v", err)
+		}
+	}
+	if _, err := store.StopStream(channel.ID, 0); err != nil {
+		t.Fatalf("StopStream: %v", err)
+	}
+	recordings, err := store.ListRecordings(channel.ID, true)
+	if err != nil || len(recordings) != 1 {
+		t.Fatalf("expected one recording, got %d (err %v)", len(recordings), err)
+	}
+	recording := recordings[0]
+	if len(recording.Chapters) != 1 || recording.Chapters[0].OffsetSeconds != 30 {
+		t.Fatalf("expected only the marker inside the recording as a chapter, got %+v", recording.Chapters)
+	}
+
+	end := recording.DurationSeconds
+	if _, err := store.SetRecordingChapters(recording.ID, []models.RecordingChapter{{OffsetSeconds: end, Title: "Too late"}}); err == nil {
+		t.Fatal("expected a chapter at the end of the recording to be rejected")
+	}
+	if _, err := store.SetRecordingChapters(recording.ID, []models.RecordingChapter{{OffsetSeconds: end - 1, Title: "Last second"}}); err != nil {
+		t.Fatalf("expected a chapter inside the recording to be accepted: %v", err)
+	}
+}
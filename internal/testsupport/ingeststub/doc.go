@@ -3,4 +3,8 @@
 // application lifecycle, and transcoder job control without touching the
 // network, enabling end-to-end ingest orchestration tests to assert control
 // calls and retries.
+//
+// Faults inject per-endpoint latency and scripted failures, and a Scenario
+// builds them declaratively. Every call, including injected failures, is
+// journaled with its arrival time so tests can assert retry timing.
 package ingeststub
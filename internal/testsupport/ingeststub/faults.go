@@ -0,0 +1,175 @@
+package ingeststub
+
+import (
+	"math/rand"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// Endpoint names one control-plane route. The values match the Kind of the
+// operations recorded for that route.
+type Endpoint string
+
+const (
+	EndpointChannelCreate     Endpoint = "channel-create"
+	EndpointChannelDelete     Endpoint = "channel-delete"
+	EndpointApplicationCreate Endpoint = "application-create"
+	EndpointApplicationDelete Endpoint = "application-delete"
+	EndpointJobStart          Endpoint = "job-start"
+	EndpointJobStop           Endpoint = "job-stop"
+	EndpointJobStatus         Endpoint = "job-status"
+	EndpointHealth            Endpoint = "health"
+)
+
+// Latency yields the delay injected before the given 1-based call to an
+// endpoint is answered.
+type Latency func(call int) time.Duration
+
+// FixedLatency delays every call by d.
+func FixedLatency(d time.Duration) Latency {
+	return func(int) time.Duration { return d }
+}
+
+// UniformLatency delays each call by a duration drawn uniformly from
+// [min, max]. The draws come from a generator seeded with seed, so a
+// scenario replays the same delays on every run.
+func UniformLatency(min, max time.Duration, seed int64) Latency {
+	if max < min {
+		min, max = max, min
+	}
+	var mu sync.Mutex
+	rng := rand.New(rand.NewSource(seed))
+	return func(int) time.Duration {
+		if max == min {
+			return min
+		}
+		mu.Lock()
+		defer mu.Unlock()
+		return min + time.Duration(rng.Int63n(int64(max-min)+1))
+	}
+}
+
+// LatencySequence delays call n by delays[n-1] and repeats the last delay
+// once the sequence is exhausted.
+func LatencySequence(delays ...time.Duration) Latency {
+	return func(call int) time.Duration {
+		if len(delays) == 0 {
+			return 0
+		}
+		if call > len(delays) {
+			call = len(delays)
+		}
+		return delays[call-1]
+	}
+}
+
+// Fault describes the misbehaviour injected into one endpoint. A call fails
+// when any of its rules match; failing calls are still delayed by Latency.
+type Fault struct {
+	// Latency delays calls before they are answered.
+	Latency Latency
+	// FailFirst fails the first N calls; later calls succeed.
+	FailFirst int
+	// FailEvery fails every Kth call.
+	FailEvery int
+	// Script lists the status for each call in order. Zero entries, and
+	// calls beyond the end of the script, are answered normally.
+	Script []int
+	// Status is the HTTP status returned by FailFirst and FailEvery
+	// failures. It defaults to 503.
+	Status int
+}
+
+// status returns the failure status for the given 1-based call, or zero when
+// the call should be answered normally.
+func (f Fault) status(call int) int {
+	failure := f.Status
+	if failure == 0 {
+		failure = http.StatusServiceUnavailable
+	}
+	if call <= len(f.Script) && f.Script[call-1] != 0 {
+		return f.Script[call-1]
+	}
+	if call <= f.FailFirst {
+		return failure
+	}
+	if f.FailEvery > 0 && call%f.FailEvery == 0 {
+		return failure
+	}
+	return 0
+}
+
+// Scenario declaratively builds a fault-injected control plane:
+//
+//	stub := ingeststub.NewScenario(ingeststub.Options{}).
+//		FailFirst(ingeststub.EndpointChannelCreate, 2, http.StatusServiceUnavailable).
+//		Latency(ingeststub.EndpointApplicationCreate, ingeststub.FixedLatency(20*time.Millisecond)).
+//		LoseStartedJobs().
+//		Start()
+//	t.Cleanup(stub.Close)
+type Scenario struct {
+	opts Options
+}
+
+// NewScenario starts a scenario from opts. Faults already present in opts
+// are kept and may be refined by the builder methods.
+func NewScenario(opts Options) *Scenario {
+	faults := make(map[Endpoint]Fault, len(opts.Faults))
+	for endpoint, fault := range opts.Faults {
+		faults[endpoint] = fault
+	}
+	opts.Faults = faults
+	return &Scenario{opts: opts}
+}
+
+func (s *Scenario) update(endpoint Endpoint, fn func(*Fault)) *Scenario {
+	fault := s.opts.Faults[endpoint]
+	fn(&fault)
+	s.opts.Faults[endpoint] = fault
+	return s
+}
+
+// Latency injects delays into calls to endpoint.
+func (s *Scenario) Latency(endpoint Endpoint, latency Latency) *Scenario {
+	return s.update(endpoint, func(f *Fault) { f.Latency = latency })
+}
+
+// FailFirst fails the first n calls to endpoint with status.
+func (s *Scenario) FailFirst(endpoint Endpoint, n, status int) *Scenario {
+	return s.update(endpoint, func(f *Fault) {
+		f.FailFirst = n
+		f.Status = status
+	})
+}
+
+// FailEvery fails every kth call to endpoint with status.
+func (s *Scenario) FailEvery(endpoint Endpoint, k, status int) *Scenario {
+	return s.update(endpoint, func(f *Fault) {
+		f.FailEvery = k
+		f.Status = status
+	})
+}
+
+// Script answers calls to endpoint with the listed statuses in order; zero
+// entries are answered normally.
+func (s *Scenario) Script(endpoint Endpoint, statuses ...int) *Scenario {
+	return s.update(endpoint, func(f *Fault) { f.Script = append([]int(nil), statuses...) })
+}
+
+// LoseStartedJobs makes job starts succeed while the jobs never come up, so
+// later status lookups and health checks report them missing.
+func (s *Scenario) LoseStartedJobs() *Scenario {
+	s.opts.LoseStartedJobs = true
+	return s
+}
+
+// Options returns the options the scenario has built so far.
+func (s *Scenario) Options() Options {
+	return s.opts
+}
+
+// Start launches a control plane for the scenario.
+func (s *Scenario) Start() *ControlPlane {
+	return Start(s.opts)
+}
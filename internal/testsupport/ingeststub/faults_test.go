@@ -0,0 +1,111 @@
+package ingeststub
+
+import (
+	"net/http"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestFaultStatusRules(t *testing.T) {
+	fault := Fault{FailFirst: 2, FailEvery: 5, Script: []int{0, 0, http.StatusTooManyRequests}, Status: http.StatusBadGateway}
+	want := map[int]int{
+		1:  http.StatusBadGateway,
+		2:  http.StatusBadGateway,
+		3:  http.StatusTooManyRequests,
+		4:  0,
+		5:  http.StatusBadGateway,
+		6:  0,
+		10: http.StatusBadGateway,
+	}
+	for call, status := range want {
+		if got := fault.status(call); got != status {
+			t.Fatalf("call %d: expected status %d, got %d", call, status, got)
+		}
+	}
+	if got := (Fault{FailFirst: 1}).status(1); got != http.StatusServiceUnavailable {
+		t.Fatalf("expected default status 503, got %d", got)
+	}
+}
+
+func TestUniformLatencyIsDeterministic(t *testing.T) {
+	first := UniformLatency(time.Millisecond, 5*time.Millisecond, 42)
+	second := UniformLatency(time.Millisecond, 5*time.Millisecond, 42)
+	for call := 1; call <= 20; call++ {
+		a, b := first(call), second(call)
+		if a != b {
+			t.Fatalf("call %d: expected equal delays, got %v and %v", call, a, b)
+		}
+		if a < time.Millisecond || a > 5*time.Millisecond {
+			t.Fatalf("call %d: delay %v outside [1ms, 5ms]", call, a)
+		}
+	}
+	sequence := LatencySequence(time.Millisecond, 2*time.Millisecond)
+	if sequence(1) != time.Millisecond || sequence(2) != 2*time.Millisecond || sequence(7) != 2*time.Millisecond {
+		t.Fatal("expected the sequence to repeat its last delay")
+	}
+}
+
+func TestScenarioJournalsInjectedFaults(t *testing.T) {
+	stub := NewScenario(Options{}).
+		FailFirst(EndpointChannelCreate, 2, http.StatusInternalServerError).
+		Latency(EndpointChannelCreate, FixedLatency(5*time.Millisecond)).
+		Start()
+	t.Cleanup(stub.Close)
+
+	for i := 0; i < 3; i++ {
+		resp, err := http.Post(stub.BaseURL()+"/v1/channels", "application/json", strings.NewReader(`{"channelId":"c1","streamKey":"k"}`))
+		if err != nil {
+			t.Fatalf("POST: %v", err)
+		}
+		resp.Body.Close()
+		want := http.StatusOK
+		if i < 2 {
+			want = http.StatusInternalServerError
+		}
+		if resp.StatusCode != want {
+			t.Fatalf("call %d: expected %d, got %d", i+1, want, resp.StatusCode)
+		}
+	}
+
+	journal := stub.Journal(EndpointChannelCreate)
+	if len(journal) != 3 {
+		t.Fatalf("expected 3 journaled calls, got %d", len(journal))
+	}
+	for i, op := range journal {
+		if op.Attempt != i+1 || op.ChannelID != "c1" || op.Latency != 5*time.Millisecond {
+			t.Fatalf("unexpected journal entry %d: %+v", i, op)
+		}
+		if op.Injected != (i < 2) {
+			t.Fatalf("entry %d: expected injected=%v", i, i < 2)
+		}
+	}
+}
+
+func TestLoseStartedJobsReportsJobsMissing(t *testing.T) {
+	stub := NewScenario(Options{LiveJobIDs: []string{"job-1"}}).LoseStartedJobs().Start()
+	t.Cleanup(stub.Close)
+
+	resp, err := http.Post(stub.BaseURL()+"/v1/jobs", "application/json", strings.NewReader(`{"channelId":"c1"}`))
+	if err != nil {
+		t.Fatalf("POST: %v", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		t.Fatalf("expected job start to succeed, got %d", resp.StatusCode)
+	}
+
+	for path, want := range map[string]int{"/v1/jobs/job-1": http.StatusNotFound, "/healthz": http.StatusServiceUnavailable} {
+		resp, err := http.Get(stub.BaseURL() + path)
+		if err != nil {
+			t.Fatalf("GET %s: %v", path, err)
+		}
+		resp.Body.Close()
+		if resp.StatusCode != want {
+			t.Fatalf("GET %s: expected %d, got %d", path, want, resp.StatusCode)
+		}
+	}
+	if missing := stub.MissingJobs(); len(missing) != 1 || missing[0] != "job-1" {
+		t.Fatalf("expected job-1 missing, got %v", missing)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"net/http"
 	"net/http/httptest"
+	"sort"
 	"strings"
 	"sync"
 	"time"
@@ -20,6 +21,11 @@ type Options struct {
 	OriginURL   string
 	PlaybackURL string
 
+	// PlaybackEndpoints are returned verbatim as playbackEndpoints from the
+	// application create endpoint. Empty omits the field, like control
+	// planes that only report playbackUrl.
+	PlaybackEndpoints []map[string]interface{}
+
 	// LiveJobIDs are returned from the job start endpoint.
 	LiveJobIDs []string
 
@@ -34,6 +40,20 @@ type Options struct {
 	// Subsequent attempts succeed.
 	FailJobStarts int
 
+	// Faults injects latency and failures per endpoint. A fault for the
+	// channel create or job start endpoint takes precedence over
+	// FailChannelCreates and FailJobStarts.
+	Faults map[Endpoint]Fault
+
+	// LoseStartedJobs accepts job starts but never runs the jobs: status
+	// lookups answer 404 and the health endpoint reports them missing.
+	LoseStartedJobs bool
+
+	// JobOutputBytes is reported as each stopped job's output size. Zero
+	// answers job stops with 204 and no body, like transcoders that predate
+	// storage accounting.
+	JobOutputBytes int64
+
 	// Expected tokens/credentials enforced by the stub. If empty, the check is
 	// skipped.
 	SRSToken        string
@@ -53,7 +73,12 @@ type Operation struct {
 	Ladder     []map[string]interface{}
 	Attempt    int
 	Status     int
-	Timestamp  time.Time
+	// Timestamp is when the request arrived, before any injected latency.
+	Timestamp time.Time
+	// Latency is the delay injected before the request was answered.
+	Latency time.Duration
+	// Injected reports that Status was a failure injected by a fault.
+	Injected bool
 }
 
 // ControlPlane hosts a single httptest.Server that serves all ingest endpoints.
@@ -63,13 +88,13 @@ type ControlPlane struct {
 
 	mu         sync.Mutex
 	operations []Operation
-	channelErr int
-	jobErr     int
+	calls      map[Endpoint]int
+	jobs       map[string]bool
 }
 
 // Start spins up a new control-plane stub using the provided options.
 func Start(opts Options) *ControlPlane {
-	cp := &ControlPlane{opts: opts}
+	cp := &ControlPlane{opts: opts, calls: make(map[Endpoint]int), jobs: make(map[string]bool)}
 	cp.server = httptest.NewServer(http.HandlerFunc(cp.handle))
 	return cp
 }
@@ -95,6 +120,35 @@ func (c *ControlPlane) Operations() []Operation {
 	return out
 }
 
+// Journal returns the recorded calls to endpoint, including injected
+// failures, in the order they arrived.
+func (c *ControlPlane) Journal(endpoint Endpoint) []Operation {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var out []Operation
+	for _, op := range c.operations {
+		if op.Kind == string(endpoint) {
+			out = append(out, op)
+		}
+	}
+	return out
+}
+
+// MissingJobs lists started jobs that never came up because of
+// LoseStartedJobs and have not been stopped.
+func (c *ControlPlane) MissingJobs() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	var missing []string
+	for jobID, running := range c.jobs {
+		if !running {
+			missing = append(missing, jobID)
+		}
+	}
+	sort.Strings(missing)
+	return missing
+}
+
 func (c *ControlPlane) handle(w http.ResponseWriter, r *http.Request) {
 	switch {
 	case r.Method == http.MethodPost && r.URL.Path == "/v1/channels":
@@ -109,6 +163,10 @@ func (c *ControlPlane) handle(w http.ResponseWriter, r *http.Request) {
 		c.handleStartJobs(w, r)
 	case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/v1/jobs/"):
 		c.handleStopJob(w, r)
+	case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/v1/jobs/"):
+		c.handleJobStatus(w, r)
+	case r.Method == http.MethodGet && r.URL.Path == "/healthz":
+		c.handleHealth(w, r)
 	default:
 		http.Error(w, "unexpected request", http.StatusNotFound)
 	}
@@ -134,27 +192,10 @@ func (c *ControlPlane) handleCreateChannel(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	c.mu.Lock()
-	c.channelErr++
-	attempt := c.channelErr
-	c.mu.Unlock()
-
-	op := Operation{
-		Kind:      "channel-create",
-		ChannelID: req.ChannelID,
-		StreamKey: req.StreamKey,
-		Attempt:   attempt,
-		Status:    http.StatusOK,
-		Timestamp: time.Now(),
-	}
-
-	if attempt <= c.opts.FailChannelCreates {
-		op.Status = http.StatusServiceUnavailable
-		c.record(op)
-		http.Error(w, "srs unavailable", http.StatusServiceUnavailable)
+	op := Operation{ChannelID: req.ChannelID, StreamKey: req.StreamKey, Status: http.StatusOK}
+	if c.inject(w, r, EndpointChannelCreate, &op, "srs unavailable") {
 		return
 	}
-
 	c.record(op)
 
 	resp := channelResponse{PrimaryIngest: c.opts.PrimaryIngest, BackupIngest: c.opts.BackupIngest}
@@ -168,8 +209,11 @@ func (c *ControlPlane) handleDeleteChannel(w http.ResponseWriter, r *http.Reques
 	if !c.expectBearer(w, r, c.opts.SRSToken) {
 		return
 	}
-	channelID := strings.TrimPrefix(r.URL.Path, "/v1/channels/")
-	c.record(Operation{Kind: "channel-delete", ChannelID: channelID, Status: http.StatusNoContent})
+	op := Operation{ChannelID: strings.TrimPrefix(r.URL.Path, "/v1/channels/"), Status: http.StatusNoContent}
+	if c.inject(w, r, EndpointChannelDelete, &op, "srs unavailable") {
+		return
+	}
+	c.record(op)
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -182,8 +226,9 @@ func (c *ControlPlane) handleCreateApplication(w http.ResponseWriter, r *http.Re
 		Renditions []string `json:"renditions"`
 	}
 	type appResponse struct {
-		OriginURL   string `json:"originUrl"`
-		PlaybackURL string `json:"playbackUrl"`
+		OriginURL         string                   `json:"originUrl"`
+		PlaybackURL       string                   `json:"playbackUrl"`
+		PlaybackEndpoints []map[string]interface{} `json:"playbackEndpoints,omitempty"`
 	}
 
 	var req appRequest
@@ -192,14 +237,13 @@ func (c *ControlPlane) handleCreateApplication(w http.ResponseWriter, r *http.Re
 		return
 	}
 
-	c.record(Operation{
-		Kind:       "application-create",
-		ChannelID:  req.ChannelID,
-		Renditions: append([]string{}, req.Renditions...),
-		Status:     http.StatusOK,
-	})
+	op := Operation{ChannelID: req.ChannelID, Renditions: append([]string{}, req.Renditions...), Status: http.StatusOK}
+	if c.inject(w, r, EndpointApplicationCreate, &op, "ome unavailable") {
+		return
+	}
+	c.record(op)
 
-	resp := appResponse{OriginURL: c.opts.OriginURL, PlaybackURL: c.opts.PlaybackURL}
+	resp := appResponse{OriginURL: c.opts.OriginURL, PlaybackURL: c.opts.PlaybackURL, PlaybackEndpoints: c.opts.PlaybackEndpoints}
 	if resp.OriginURL == "" {
 		resp.OriginURL = "http://origin"
 	}
@@ -213,8 +257,11 @@ func (c *ControlPlane) handleDeleteApplication(w http.ResponseWriter, r *http.Re
 	if !c.expectBasic(w, r, c.opts.OMEUser, c.opts.OMEPassword) {
 		return
 	}
-	channelID := strings.TrimPrefix(r.URL.Path, "/v1/applications/")
-	c.record(Operation{Kind: "application-delete", ChannelID: channelID, Status: http.StatusNoContent})
+	op := Operation{ChannelID: strings.TrimPrefix(r.URL.Path, "/v1/applications/"), Status: http.StatusNoContent}
+	if c.inject(w, r, EndpointApplicationDelete, &op, "ome unavailable") {
+		return
+	}
+	c.record(op)
 	w.WriteHeader(http.StatusNoContent)
 }
 
@@ -239,28 +286,15 @@ func (c *ControlPlane) handleStartJobs(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	c.mu.Lock()
-	c.jobErr++
-	attempt := c.jobErr
-	c.mu.Unlock()
-
 	op := Operation{
-		Kind:      "job-start",
 		ChannelID: req.ChannelID,
 		SessionID: req.SessionID,
 		Ladder:    cloneAnyRenditions(req.Renditions),
-		Attempt:   attempt,
 		Status:    http.StatusOK,
-		Timestamp: time.Now(),
 	}
-
-	if attempt <= c.opts.FailJobStarts {
-		op.Status = http.StatusBadGateway
-		c.record(op)
-		http.Error(w, "transcoder offline", http.StatusBadGateway)
+	if c.inject(w, r, EndpointJobStart, &op, "transcoder offline") {
 		return
 	}
-
 	c.record(op)
 
 	renditions := req.Renditions
@@ -272,6 +306,11 @@ func (c *ControlPlane) handleStartJobs(w http.ResponseWriter, r *http.Request) {
 	if len(resp.JobIDs) == 0 {
 		resp.JobIDs = []string{"job-live-1"}
 	}
+	c.mu.Lock()
+	for _, jobID := range resp.JobIDs {
+		c.jobs[jobID] = !c.opts.LoseStartedJobs
+	}
+	c.mu.Unlock()
 
 	_ = json.NewEncoder(w).Encode(resp)
 }
@@ -280,11 +319,111 @@ func (c *ControlPlane) handleStopJob(w http.ResponseWriter, r *http.Request) {
 	if !c.expectBearer(w, r, c.opts.TranscoderToken) {
 		return
 	}
-	jobID := strings.TrimPrefix(r.URL.Path, "/v1/jobs/")
-	c.record(Operation{Kind: "job-stop", JobID: jobID, Status: http.StatusNoContent})
+	op := Operation{JobID: strings.TrimPrefix(r.URL.Path, "/v1/jobs/"), Status: http.StatusNoContent}
+	if c.inject(w, r, EndpointJobStop, &op, "transcoder offline") {
+		return
+	}
+	c.mu.Lock()
+	delete(c.jobs, op.JobID)
+	c.mu.Unlock()
+	if c.opts.JobOutputBytes > 0 {
+		op.Status = http.StatusOK
+		c.record(op)
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(map[string]interface{}{"jobId": op.JobID, "outputBytes": c.opts.JobOutputBytes})
+		return
+	}
+	c.record(op)
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (c *ControlPlane) handleJobStatus(w http.ResponseWriter, r *http.Request) {
+	if !c.expectBearer(w, r, c.opts.TranscoderToken) {
+		return
+	}
+	op := Operation{JobID: strings.TrimPrefix(r.URL.Path, "/v1/jobs/"), Status: http.StatusOK}
+	if c.inject(w, r, EndpointJobStatus, &op, "transcoder offline") {
+		return
+	}
+	c.mu.Lock()
+	running := c.jobs[op.JobID]
+	c.mu.Unlock()
+	if !running {
+		op.Status = http.StatusNotFound
+		c.record(op)
+		http.Error(w, "job not found", http.StatusNotFound)
+		return
+	}
+	c.record(op)
+	_ = json.NewEncoder(w).Encode(map[string]string{"jobId": op.JobID, "status": "running"})
+}
+
+func (c *ControlPlane) handleHealth(w http.ResponseWriter, r *http.Request) {
+	op := Operation{Status: http.StatusOK}
+	if c.inject(w, r, EndpointHealth, &op, "unhealthy") {
+		return
+	}
+	if missing := c.MissingJobs(); len(missing) > 0 {
+		op.Status = http.StatusServiceUnavailable
+		c.record(op)
+		http.Error(w, "jobs missing: "+strings.Join(missing, ","), http.StatusServiceUnavailable)
+		return
+	}
+	c.record(op)
+	w.WriteHeader(http.StatusOK)
+}
+
+// fault returns the fault configured for endpoint, folding in the legacy
+// FailChannelCreates and FailJobStarts options.
+func (c *ControlPlane) fault(endpoint Endpoint) Fault {
+	fault, ok := c.opts.Faults[endpoint]
+	if ok {
+		return fault
+	}
+	switch endpoint {
+	case EndpointChannelCreate:
+		fault = Fault{FailFirst: c.opts.FailChannelCreates, Status: http.StatusServiceUnavailable}
+	case EndpointJobStart:
+		fault = Fault{FailFirst: c.opts.FailJobStarts, Status: http.StatusBadGateway}
+	}
+	return fault
+}
+
+// inject numbers the call, applies the endpoint's latency, and answers it
+// with an injected failure when the fault says so. It reports whether the
+// response was written; otherwise op carries the call's kind, attempt,
+// arrival time, and latency for the handler to record.
+func (c *ControlPlane) inject(w http.ResponseWriter, r *http.Request, endpoint Endpoint, op *Operation, message string) bool {
+	op.Kind = string(endpoint)
+	op.Timestamp = time.Now()
+	c.mu.Lock()
+	c.calls[endpoint]++
+	op.Attempt = c.calls[endpoint]
+	c.mu.Unlock()
+
+	fault := c.fault(endpoint)
+	if fault.Latency != nil {
+		op.Latency = fault.Latency(op.Attempt)
+		if op.Latency > 0 {
+			timer := time.NewTimer(op.Latency)
+			select {
+			case <-timer.C:
+			case <-r.Context().Done():
+				timer.Stop()
+			}
+		}
+	}
+	status := fault.status(op.Attempt)
+	if status == 0 {
+		return false
+	}
+	op.Status = status
+	op.Injected = true
+	c.record(*op)
+	http.Error(w, message, status)
+	return true
+}
+
 func (c *ControlPlane) record(op Operation) {
 	if op.Timestamp.IsZero() {
 		op.Timestamp = time.Now()
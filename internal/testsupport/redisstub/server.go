@@ -10,6 +10,7 @@ import (
 	"fmt"
 	"math/big"
 	"net"
+	"sort"
 	"strconv"
 	"strings"
 	"sync"
@@ -38,6 +39,9 @@ type Server struct {
 	mu       sync.Mutex
 	streams  map[string]*redisStream
 	kv       map[string]*kvEntry
+	hashes   map[string]*hashEntry
+	offset   time.Duration
+	conns    map[net.Conn]struct{}
 	closed   chan struct{}
 	tlsCert  tls.Certificate
 	certPEM  []byte
@@ -58,7 +62,13 @@ type streamEntry struct {
 
 type groupState struct {
 	nextIndex int
-	pending   map[string]struct{}
+	pending   map[string]*pendingEntry
+}
+
+type pendingEntry struct {
+	consumer    string
+	deliveredAt time.Time
+	deliveries  int64
 }
 
 type kvEntry struct {
@@ -66,6 +76,11 @@ type kvEntry struct {
 	expiry time.Time
 }
 
+type hashEntry struct {
+	fields map[string]string
+	expiry time.Time
+}
+
 func Start(opts Options) (*Server, error) {
 	var ln net.Listener
 	var err error
@@ -73,6 +88,8 @@ func Start(opts Options) (*Server, error) {
 		opts:    opts,
 		streams: make(map[string]*redisStream),
 		kv:      make(map[string]*kvEntry),
+		hashes:  make(map[string]*hashEntry),
+		conns:   make(map[net.Conn]struct{}),
 		closed:  make(chan struct{}),
 		hooks:   opts.Hooks,
 	}
@@ -111,6 +128,14 @@ func (s *Server) KeyPEM() []byte {
 	return s.keyPEM
 }
 
+// FastForward advances the clock used to expire hash keys, letting tests
+// observe TTL expiry without sleeping.
+func (s *Server) FastForward(d time.Duration) {
+	s.mu.Lock()
+	s.offset += d
+	s.mu.Unlock()
+}
+
 func (s *Server) Close() error {
 	s.mu.Lock()
 	select {
@@ -120,10 +145,17 @@ func (s *Server) Close() error {
 	default:
 	}
 	close(s.closed)
+	conns := make([]net.Conn, 0, len(s.conns))
+	for conn := range s.conns {
+		conns = append(conns, conn)
+	}
 	s.mu.Unlock()
 	if s.listener != nil {
 		_ = s.listener.Close()
 	}
+	for _, conn := range conns {
+		_ = conn.Close()
+	}
 	return nil
 }
 
@@ -143,12 +175,29 @@ func (s *Server) serve() {
 }
 
 func (s *Server) handleConnection(conn net.Conn) {
+	s.mu.Lock()
+	select {
+	case <-s.closed:
+		s.mu.Unlock()
+		_ = conn.Close()
+		return
+	default:
+	}
+	s.conns[conn] = struct{}{}
+	s.mu.Unlock()
 	defer func() {
+		s.mu.Lock()
+		delete(s.conns, conn)
+		s.mu.Unlock()
 		_ = conn.Close()
 	}()
 	reader := bufio.NewReader(conn)
 	writer := bufio.NewWriter(conn)
 	authenticated := s.opts.Password == ""
+	var (
+		inMulti bool
+		queued  [][]string
+	)
 	for {
 		args, err := readArray(reader)
 		if err != nil {
@@ -210,6 +259,42 @@ func (s *Server) handleConnection(conn net.Conn) {
 				}
 				continue
 			}
+			if inMulti {
+				switch cmd {
+				case "EXEC":
+					replies := make([]interface{}, 0, len(queued))
+					for _, queuedArgs := range queued {
+						reply, err := s.execute(queuedArgs)
+						if err != nil {
+							replies = append(replies, err)
+							continue
+						}
+						replies = append(replies, reply)
+					}
+					inMulti, queued = false, nil
+					if err := writeArray(writer, replies); err != nil {
+						return
+					}
+				case "DISCARD":
+					inMulti, queued = false, nil
+					if err := writeSimpleString(writer, "OK"); err != nil {
+						return
+					}
+				default:
+					queued = append(queued, args)
+					if err := writeSimpleString(writer, "QUEUED"); err != nil {
+						return
+					}
+				}
+				continue
+			}
+			if cmd == "MULTI" {
+				inMulti = true
+				if err := writeSimpleString(writer, "OK"); err != nil {
+					return
+				}
+				continue
+			}
 			if !s.dispatch(writer, args) {
 				return
 			}
@@ -224,29 +309,6 @@ func (s *Server) dispatch(writer *bufio.Writer, args []string) bool {
 	}
 	cmd := strings.ToUpper(args[0])
 	switch cmd {
-	case "XADD":
-		if len(args) < 5 {
-			_ = writeError(writer, "ERR wrong number of arguments for 'xadd'")
-			return false
-		}
-		stream := args[1]
-		id := args[2]
-		if id == "*" {
-			id = fmt.Sprintf("%d-0", time.Now().UnixNano())
-		}
-		values := make(map[string]string)
-		for i := 3; i+1 < len(args); i += 2 {
-			values[args[i]] = args[i+1]
-		}
-		s.mu.Lock()
-		strm := s.ensureStream(stream)
-		strm.entries = append(strm.entries, streamEntry{id: id, values: values})
-		s.mu.Unlock()
-		s.notifyXAdd(stream, values)
-		if err := writeBulkString(writer, id); err != nil {
-			return false
-		}
-		return true
 	case "XGROUP":
 		if len(args) < 5 {
 			_ = writeError(writer, "ERR wrong number of arguments for 'xgroup'")
@@ -269,7 +331,7 @@ func (s *Server) dispatch(writer *bufio.Writer, args []string) bool {
 			_ = writeError(writer, "BUSYGROUP Consumer Group name already exists")
 			return false
 		}
-		strm.groups[group] = &groupState{pending: make(map[string]struct{})}
+		strm.groups[group] = &groupState{pending: make(map[string]*pendingEntry)}
 		s.mu.Unlock()
 		if err := writeSimpleString(writer, "OK"); err != nil {
 			return false
@@ -277,27 +339,29 @@ func (s *Server) dispatch(writer *bufio.Writer, args []string) bool {
 		return true
 	case "XREADGROUP":
 		return s.handleXReadGroup(writer, args)
-	case "XACK":
-		if len(args) < 4 {
-			_ = writeError(writer, "ERR wrong number of arguments for 'xack'")
+	case "INCR":
+		if len(args) != 2 {
+			_ = writeError(writer, "ERR wrong number of arguments for 'incr'")
 			return false
 		}
-		stream := args[1]
-		group := args[2]
-		ids := args[3:]
-		acked := s.ack(stream, group, ids)
-		if err := writeInteger(writer, int64(len(acked))); err != nil {
+		value := s.incr(args[1])
+		if err := writeInteger(writer, value); err != nil {
 			return false
 		}
-		s.notifyXAck(stream, group, acked)
 		return true
-	case "INCR":
+	case "GET":
 		if len(args) != 2 {
-			_ = writeError(writer, "ERR wrong number of arguments for 'incr'")
+			_ = writeError(writer, "ERR wrong number of arguments for 'get'")
 			return false
 		}
-		value := s.incr(args[1])
-		if err := writeInteger(writer, value); err != nil {
+		value, ok := s.get(args[1])
+		if !ok {
+			if err := writeBulkNil(writer); err != nil {
+				return false
+			}
+			return true
+		}
+		if err := writeBulkString(writer, strconv.FormatInt(value, 10)); err != nil {
 			return false
 		}
 		return true
@@ -316,6 +380,17 @@ func (s *Server) dispatch(writer *bufio.Writer, args []string) bool {
 			return false
 		}
 		return true
+	case "XADD", "XACK", "XDEL", "XLEN", "XRANGE", "XPENDING", "XAUTOCLAIM", "XINFO",
+		"HSET", "HGET", "HDEL", "HGETALL", "PEXPIREAT", "PTTL", "DEL":
+		reply, err := s.execute(args)
+		if err != nil {
+			_ = writeError(writer, err.Error())
+			return false
+		}
+		if err := writeReply(writer, reply); err != nil {
+			return false
+		}
+		return true
 	case "TTL":
 		if len(args) != 2 {
 			_ = writeError(writer, "ERR wrong number of arguments for 'ttl'")
@@ -332,6 +407,428 @@ func (s *Server) dispatch(writer *bufio.Writer, args []string) bool {
 	}
 }
 
+// execute runs the key and hash commands that may also be queued inside a
+// MULTI block, returning the reply instead of writing it.
+func (s *Server) execute(args []string) (interface{}, error) {
+	cmd := strings.ToUpper(args[0])
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	now := s.nowLocked()
+	switch cmd {
+	case "XADD":
+		if len(args) < 5 {
+			return nil, fmt.Errorf("ERR wrong number of arguments for 'xadd'")
+		}
+		stream := args[1]
+		strm := s.ensureStream(stream)
+		id := args[2]
+		if id == "*" {
+			id = s.nextStreamIDLocked(strm)
+		}
+		values := make(map[string]string)
+		for i := 3; i+1 < len(args); i += 2 {
+			values[args[i]] = args[i+1]
+		}
+		strm.entries = append(strm.entries, streamEntry{id: id, values: values})
+		s.notifyXAdd(stream, values)
+		return id, nil
+	case "XACK":
+		if len(args) < 4 {
+			return nil, fmt.Errorf("ERR wrong number of arguments for 'xack'")
+		}
+		acked := s.ackLocked(args[1], args[2], args[3:])
+		s.notifyXAck(args[1], args[2], acked)
+		return int64(len(acked)), nil
+	case "XDEL":
+		if len(args) < 3 {
+			return nil, fmt.Errorf("ERR wrong number of arguments for 'xdel'")
+		}
+		strm, ok := s.streams[args[1]]
+		if !ok {
+			return int64(0), nil
+		}
+		var removed int64
+		for _, id := range args[2:] {
+			for i, entry := range strm.entries {
+				if entry.id != id {
+					continue
+				}
+				strm.entries = append(strm.entries[:i], strm.entries[i+1:]...)
+				for _, state := range strm.groups {
+					if state.nextIndex > i {
+						state.nextIndex--
+					}
+				}
+				removed++
+				break
+			}
+		}
+		return removed, nil
+	case "XLEN":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("ERR wrong number of arguments for 'xlen'")
+		}
+		if strm, ok := s.streams[args[1]]; ok {
+			return int64(len(strm.entries)), nil
+		}
+		return int64(0), nil
+	case "XRANGE":
+		if len(args) != 4 && len(args) != 6 {
+			return nil, fmt.Errorf("ERR wrong number of arguments for 'xrange'")
+		}
+		limit := -1
+		if len(args) == 6 {
+			n, err := strconv.Atoi(args[5])
+			if err != nil || !strings.EqualFold(args[4], "COUNT") {
+				return nil, fmt.Errorf("ERR syntax error")
+			}
+			limit = n
+		}
+		records := []interface{}{}
+		strm, ok := s.streams[args[1]]
+		if !ok {
+			return records, nil
+		}
+		for _, entry := range strm.entries {
+			if limit >= 0 && len(records) >= limit {
+				break
+			}
+			if args[2] != "-" && compareStreamIDs(entry.id, args[2]) < 0 {
+				continue
+			}
+			if args[3] != "+" && compareStreamIDs(entry.id, args[3]) > 0 {
+				continue
+			}
+			records = append(records, []interface{}{entry.id, flatten(entry.values)})
+		}
+		return records, nil
+	case "XPENDING":
+		return s.pendingLocked(args, now)
+	case "XAUTOCLAIM":
+		return s.autoClaimLocked(args, now)
+	case "XINFO":
+		if len(args) != 3 || !strings.EqualFold(args[1], "GROUPS") {
+			return nil, fmt.Errorf("ERR only XINFO GROUPS supported")
+		}
+		strm, ok := s.streams[args[2]]
+		if !ok {
+			return nil, fmt.Errorf("ERR no such key")
+		}
+		names := make([]string, 0, len(strm.groups))
+		for name := range strm.groups {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		groups := make([]interface{}, 0, len(names))
+		for _, name := range names {
+			state := strm.groups[name]
+			lag := int64(len(strm.entries) - state.nextIndex)
+			if lag < 0 {
+				lag = 0
+			}
+			groups = append(groups, []interface{}{
+				"name", name,
+				"pending", int64(len(state.pending)),
+				"lag", lag,
+			})
+		}
+		return groups, nil
+	case "HGET":
+		if len(args) != 3 {
+			return nil, fmt.Errorf("ERR wrong number of arguments for 'hget'")
+		}
+		entry := s.hashLocked(args[1], now)
+		if entry == nil {
+			return nil, nil
+		}
+		value, ok := entry.fields[args[2]]
+		if !ok {
+			return nil, nil
+		}
+		return value, nil
+	case "HDEL":
+		if len(args) < 3 {
+			return nil, fmt.Errorf("ERR wrong number of arguments for 'hdel'")
+		}
+		entry := s.hashLocked(args[1], now)
+		if entry == nil {
+			return int64(0), nil
+		}
+		var removed int64
+		for _, field := range args[2:] {
+			if _, ok := entry.fields[field]; ok {
+				delete(entry.fields, field)
+				removed++
+			}
+		}
+		if len(entry.fields) == 0 {
+			delete(s.hashes, args[1])
+		}
+		return removed, nil
+	case "HSET":
+		if len(args) < 4 || len(args)%2 != 0 {
+			return nil, fmt.Errorf("ERR wrong number of arguments for 'hset'")
+		}
+		entry := s.hashLocked(args[1], now)
+		if entry == nil {
+			entry = &hashEntry{fields: make(map[string]string)}
+			s.hashes[args[1]] = entry
+		}
+		var added int64
+		for i := 2; i+1 < len(args); i += 2 {
+			if _, exists := entry.fields[args[i]]; !exists {
+				added++
+			}
+			entry.fields[args[i]] = args[i+1]
+		}
+		return added, nil
+	case "HGETALL":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("ERR wrong number of arguments for 'hgetall'")
+		}
+		entry := s.hashLocked(args[1], now)
+		if entry == nil {
+			return []interface{}{}, nil
+		}
+		return flatten(entry.fields), nil
+	case "PEXPIREAT":
+		if len(args) != 3 {
+			return nil, fmt.Errorf("ERR wrong number of arguments for 'pexpireat'")
+		}
+		millis, err := strconv.ParseInt(args[2], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("ERR value is not an integer or out of range")
+		}
+		entry := s.hashLocked(args[1], now)
+		if entry == nil {
+			return int64(0), nil
+		}
+		entry.expiry = time.UnixMilli(millis)
+		if !now.Before(entry.expiry) {
+			delete(s.hashes, args[1])
+		}
+		return int64(1), nil
+	case "PTTL":
+		if len(args) != 2 {
+			return nil, fmt.Errorf("ERR wrong number of arguments for 'pttl'")
+		}
+		entry := s.hashLocked(args[1], now)
+		switch {
+		case entry == nil:
+			return int64(-2), nil
+		case entry.expiry.IsZero():
+			return int64(-1), nil
+		default:
+			return entry.expiry.Sub(now).Milliseconds(), nil
+		}
+	case "DEL":
+		if len(args) < 2 {
+			return nil, fmt.Errorf("ERR wrong number of arguments for 'del'")
+		}
+		var removed int64
+		for _, key := range args[1:] {
+			if s.hashLocked(key, now) != nil {
+				delete(s.hashes, key)
+				removed++
+			}
+			if _, ok := s.kv[key]; ok {
+				delete(s.kv, key)
+				removed++
+			}
+		}
+		return removed, nil
+	default:
+		return nil, fmt.Errorf("ERR unsupported command")
+	}
+}
+
+// pendingLocked implements the summary form of XPENDING (stream and group
+// only) and the extended form with a start, end, count, and optional consumer.
+func (s *Server) pendingLocked(args []string, now time.Time) (interface{}, error) {
+	if len(args) != 3 && len(args) != 6 && len(args) != 7 {
+		return nil, fmt.Errorf("ERR wrong number of arguments for 'xpending'")
+	}
+	state := s.groupLocked(args[1], args[2])
+	if state == nil {
+		return nil, fmt.Errorf("NOGROUP No such key or consumer group")
+	}
+	ids := s.pendingIDsLocked(state)
+	if len(args) == 3 {
+		if len(ids) == 0 {
+			return []interface{}{int64(0), nil, nil, []interface{}{}}, nil
+		}
+		perConsumer := make(map[string]int64)
+		for _, id := range ids {
+			perConsumer[state.pending[id].consumer]++
+		}
+		consumers := make([]string, 0, len(perConsumer))
+		for name := range perConsumer {
+			consumers = append(consumers, name)
+		}
+		sort.Strings(consumers)
+		counts := make([]interface{}, 0, len(consumers))
+		for _, name := range consumers {
+			counts = append(counts, []interface{}{name, strconv.FormatInt(perConsumer[name], 10)})
+		}
+		return []interface{}{int64(len(ids)), ids[0], ids[len(ids)-1], counts}, nil
+	}
+	limit, err := strconv.Atoi(args[5])
+	if err != nil {
+		return nil, fmt.Errorf("ERR value is not an integer or out of range")
+	}
+	consumer := ""
+	if len(args) == 7 {
+		consumer = args[6]
+	}
+	out := []interface{}{}
+	for _, id := range ids {
+		if len(out) >= limit {
+			break
+		}
+		if args[3] != "-" && compareStreamIDs(id, args[3]) < 0 {
+			continue
+		}
+		if args[4] != "+" && compareStreamIDs(id, args[4]) > 0 {
+			continue
+		}
+		entry := state.pending[id]
+		if consumer != "" && entry.consumer != consumer {
+			continue
+		}
+		out = append(out, []interface{}{id, entry.consumer, now.Sub(entry.deliveredAt).Milliseconds(), entry.deliveries})
+	}
+	return out, nil
+}
+
+// autoClaimLocked implements XAUTOCLAIM stream group consumer min-idle start
+// [COUNT n], transferring idle pending entries to consumer and bumping their
+// delivery counts. Pending entries whose stream entry was deleted are dropped
+// from the pending list and reported as deleted, as Redis 7 does.
+func (s *Server) autoClaimLocked(args []string, now time.Time) (interface{}, error) {
+	if len(args) != 6 && len(args) != 8 {
+		return nil, fmt.Errorf("ERR wrong number of arguments for 'xautoclaim'")
+	}
+	state := s.groupLocked(args[1], args[2])
+	if state == nil {
+		return nil, fmt.Errorf("NOGROUP No such key or consumer group")
+	}
+	minIdle, err := strconv.ParseInt(args[4], 10, 64)
+	if err != nil {
+		return nil, fmt.Errorf("ERR Invalid min-idle-time argument for XAUTOCLAIM")
+	}
+	limit := 100
+	if len(args) == 8 {
+		if limit, err = strconv.Atoi(args[7]); err != nil || !strings.EqualFold(args[6], "COUNT") {
+			return nil, fmt.Errorf("ERR syntax error")
+		}
+	}
+	strm := s.streams[args[1]]
+	values := make(map[string]map[string]string, len(strm.entries))
+	for _, entry := range strm.entries {
+		values[entry.id] = entry.values
+	}
+	claimed := []interface{}{}
+	deleted := []interface{}{}
+	for _, id := range s.pendingIDsLocked(state) {
+		if len(claimed) >= limit {
+			break
+		}
+		if args[5] != "0-0" && args[5] != "-" && compareStreamIDs(id, args[5]) < 0 {
+			continue
+		}
+		entry := state.pending[id]
+		if now.Sub(entry.deliveredAt).Milliseconds() < minIdle {
+			continue
+		}
+		fields, ok := values[id]
+		if !ok {
+			delete(state.pending, id)
+			deleted = append(deleted, id)
+			continue
+		}
+		entry.consumer = args[3]
+		entry.deliveredAt = now
+		entry.deliveries++
+		claimed = append(claimed, []interface{}{id, flatten(fields)})
+	}
+	return []interface{}{"0-0", claimed, deleted}, nil
+}
+
+func (s *Server) groupLocked(stream, group string) *groupState {
+	strm, ok := s.streams[stream]
+	if !ok {
+		return nil
+	}
+	return strm.groups[group]
+}
+
+// pendingIDsLocked lists a group's pending IDs in stream order.
+func (s *Server) pendingIDsLocked(state *groupState) []string {
+	ids := make([]string, 0, len(state.pending))
+	for id := range state.pending {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool { return compareStreamIDs(ids[i], ids[j]) < 0 })
+	return ids
+}
+
+// nextStreamIDLocked generates an auto ID that always sorts after the last
+// entry, mirroring Redis' millisecond-sequence scheme.
+func (s *Server) nextStreamIDLocked(strm *redisStream) string {
+	ms := s.nowLocked().UnixMilli()
+	seq := int64(0)
+	if n := len(strm.entries); n > 0 {
+		lastMs, lastSeq := parseStreamID(strm.entries[n-1].id)
+		if lastMs >= ms {
+			ms, seq = lastMs, lastSeq+1
+		}
+	}
+	return fmt.Sprintf("%d-%d", ms, seq)
+}
+
+func parseStreamID(id string) (int64, int64) {
+	msPart, seqPart, _ := strings.Cut(id, "-")
+	ms, _ := strconv.ParseInt(msPart, 10, 64)
+	seq, _ := strconv.ParseInt(seqPart, 10, 64)
+	return ms, seq
+}
+
+func compareStreamIDs(a, b string) int {
+	aMs, aSeq := parseStreamID(a)
+	bMs, bSeq := parseStreamID(b)
+	switch {
+	case aMs != bMs:
+		if aMs < bMs {
+			return -1
+		}
+		return 1
+	case aSeq != bSeq:
+		if aSeq < bSeq {
+			return -1
+		}
+		return 1
+	default:
+		return 0
+	}
+}
+
+// hashLocked returns the live hash stored at key, dropping it when expired.
+func (s *Server) hashLocked(key string, now time.Time) *hashEntry {
+	entry, ok := s.hashes[key]
+	if !ok {
+		return nil
+	}
+	if !entry.expiry.IsZero() && !now.Before(entry.expiry) {
+		delete(s.hashes, key)
+		return nil
+	}
+	return entry
+}
+
+func (s *Server) nowLocked() time.Time {
+	return time.Now().Add(s.offset)
+}
+
 func (s *Server) ensureStream(name string) *redisStream {
 	strm, ok := s.streams[name]
 	if !ok {
@@ -349,7 +846,7 @@ func (s *Server) handleXReadGroup(writer *bufio.Writer, args []string) bool {
 		_ = writeError(writer, "ERR wrong number of arguments for 'xreadgroup'")
 		return false
 	}
-	var group, stream string
+	var group, consumer, stream string
 	count := 1
 	blockMs := 0
 	for i := 1; i < len(args); i++ {
@@ -361,7 +858,7 @@ func (s *Server) handleXReadGroup(writer *bufio.Writer, args []string) bool {
 				return false
 			}
 			group = args[i+1]
-			_ = args[i+2]
+			consumer = args[i+2]
 			i += 2
 		case "COUNT":
 			if i+1 >= len(args) {
@@ -402,7 +899,7 @@ func (s *Server) handleXReadGroup(writer *bufio.Writer, args []string) bool {
 	}
 	deadline := time.Now().Add(time.Duration(blockMs) * time.Millisecond)
 	for {
-		items, ids := s.readGroup(stream, group, count)
+		items, ids := s.readGroup(stream, group, consumer, count)
 		if len(items) > 0 {
 			if err := writeArray(writer, []interface{}{items}); err != nil {
 				return false
@@ -420,12 +917,13 @@ func (s *Server) handleXReadGroup(writer *bufio.Writer, args []string) bool {
 	}
 }
 
-func (s *Server) readGroup(stream, group string, count int) ([]interface{}, []string) {
+func (s *Server) readGroup(stream, group, consumer string, count int) ([]interface{}, []string) {
 	s.mu.Lock()
+	now := s.nowLocked()
 	strm := s.ensureStream(stream)
 	state, ok := strm.groups[group]
 	if !ok {
-		state = &groupState{pending: make(map[string]struct{})}
+		state = &groupState{pending: make(map[string]*pendingEntry)}
 		strm.groups[group] = state
 	}
 	start := state.nextIndex
@@ -441,7 +939,7 @@ func (s *Server) readGroup(stream, group string, count int) ([]interface{}, []st
 	ids := make([]string, 0, end-start)
 	for i := start; i < end; i++ {
 		entry := strm.entries[i]
-		state.pending[entry.id] = struct{}{}
+		state.pending[entry.id] = &pendingEntry{consumer: consumer, deliveredAt: now, deliveries: 1}
 		ids = append(ids, entry.id)
 		records = append(records, []interface{}{
 			entry.id,
@@ -461,16 +959,13 @@ func flatten(values map[string]string) []interface{} {
 	return out
 }
 
-func (s *Server) ack(stream, group string, ids []string) []string {
-	s.mu.Lock()
+func (s *Server) ackLocked(stream, group string, ids []string) []string {
 	strm, ok := s.streams[stream]
 	if !ok {
-		s.mu.Unlock()
 		return nil
 	}
 	state, ok := strm.groups[group]
 	if !ok {
-		s.mu.Unlock()
 		return nil
 	}
 	acked := make([]string, 0, len(ids))
@@ -480,7 +975,6 @@ func (s *Server) ack(stream, group string, ids []string) []string {
 			acked = append(acked, id)
 		}
 	}
-	s.mu.Unlock()
 	return acked
 }
 
@@ -496,6 +990,16 @@ func (s *Server) incr(key string) int64 {
 	return entry.value
 }
 
+func (s *Server) get(key string) (int64, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	entry := s.kv[key]
+	if entry == nil || (entry.expiry.After(time.Time{}) && time.Now().After(entry.expiry)) {
+		return 0, false
+	}
+	return entry.value, true
+}
+
 func (s *Server) expire(key string, ttl time.Duration) {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -680,6 +1184,14 @@ func writeArray(w *bufio.Writer, values []interface{}) error {
 			if err := writeArray(w, v); err != nil {
 				return err
 			}
+		case error:
+			if _, err := fmt.Fprintf(w, "-%s\r\n", v.Error()); err != nil {
+				return err
+			}
+		case nil:
+			if _, err := w.WriteString("$-1\r\n"); err != nil {
+				return err
+			}
 		default:
 			if err := writeBulkStringRaw(w, fmt.Sprint(v)); err != nil {
 				return err
@@ -689,6 +1201,20 @@ func writeArray(w *bufio.Writer, values []interface{}) error {
 	return w.Flush()
 }
 
+// writeReply writes a single reply value of any type writeArray supports.
+func writeReply(w *bufio.Writer, value interface{}) error {
+	switch v := value.(type) {
+	case int64:
+		return writeInteger(w, v)
+	case []interface{}:
+		return writeArray(w, v)
+	case nil:
+		return writeBulkNil(w)
+	default:
+		return writeBulkString(w, fmt.Sprint(v))
+	}
+}
+
 func writeBulkStringRaw(w *bufio.Writer, value string) error {
 	if _, err := fmt.Fprintf(w, "$%d\r\n%s\r\n", len(value), value); err != nil {
 		return err
@@ -21,9 +21,11 @@ func NewSessionStoreStub() *SessionStoreStub {
 }
 
 // Save records the session details for the provided token.
-func (s *SessionStoreStub) Save(token, userID string, expiresAt, absoluteExpiresAt time.Time) error {
+func (s *SessionStoreStub) Save(record auth.SessionRecord) error {
+	record.ExpiresAt = record.ExpiresAt.UTC()
+	record.AbsoluteExpiresAt = record.AbsoluteExpiresAt.UTC()
 	s.mu.Lock()
-	s.sessions[token] = auth.SessionRecord{Token: token, UserID: userID, ExpiresAt: expiresAt.UTC(), AbsoluteExpiresAt: absoluteExpiresAt.UTC()}
+	s.sessions[record.Token] = record
 	s.mu.Unlock()
 	return nil
 }
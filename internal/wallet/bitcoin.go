@@ -0,0 +1,171 @@
+package wallet
+
+import (
+	"bytes"
+	"crypto/sha256"
+	"errors"
+	"math/big"
+	"strings"
+)
+
+const base58Alphabet = "123456789ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz"
+
+const bech32Charset = "qpzry9x8gf2tvdw0s3jn54khce6mua7l"
+
+// Bech32 checksum constants for witness version 0 (BIP-173) and later
+// versions (BIP-350).
+const (
+	bech32Const  = 1
+	bech32mConst = 0x2bc830a3
+)
+
+// Mainnet base58check version bytes.
+const (
+	bitcoinP2PKHVersion = 0x00
+	bitcoinP2SHVersion  = 0x05
+)
+
+// NormalizeBitcoinAddress validates a mainnet Bitcoin address and returns
+// its canonical form. Legacy addresses are checked against their base58check
+// checksum; segwit addresses against their bech32 or bech32m checksum and
+// are returned in lowercase.
+func NormalizeBitcoinAddress(address string) (string, error) {
+	if strings.HasPrefix(strings.ToLower(address), "bc1") {
+		return normalizeSegwitAddress(address)
+	}
+	payload, err := decodeBase58Check(address)
+	if err != nil {
+		return "", err
+	}
+	if len(payload) != 21 || (payload[0] != bitcoinP2PKHVersion && payload[0] != bitcoinP2SHVersion) {
+		return "", errors.New("bitcoin address has an unknown version")
+	}
+	return address, nil
+}
+
+func decodeBase58(value string) ([]byte, error) {
+	n := new(big.Int)
+	radix := big.NewInt(58)
+	for _, c := range value {
+		digit := strings.IndexRune(base58Alphabet, c)
+		if digit < 0 {
+			return nil, errors.New("address contains characters outside the base58 alphabet")
+		}
+		n.Mul(n, radix).Add(n, big.NewInt(int64(digit)))
+	}
+	decoded := n.Bytes()
+	zeros := 0
+	for zeros < len(value) && value[zeros] == base58Alphabet[0] {
+		zeros++
+	}
+	return append(make([]byte, zeros), decoded...), nil
+}
+
+func decodeBase58Check(value string) ([]byte, error) {
+	decoded, err := decodeBase58(value)
+	if err != nil {
+		return nil, err
+	}
+	if len(decoded) < 5 {
+		return nil, errors.New("bitcoin address is too short")
+	}
+	payload, checksum := decoded[:len(decoded)-4], decoded[len(decoded)-4:]
+	first := sha256.Sum256(payload)
+	second := sha256.Sum256(first[:])
+	if !bytes.Equal(second[:4], checksum) {
+		return nil, errors.New("bitcoin address has an invalid checksum")
+	}
+	return payload, nil
+}
+
+func normalizeSegwitAddress(address string) (string, error) {
+	lower := strings.ToLower(address)
+	if address != lower && address != strings.ToUpper(address) {
+		return "", errors.New("bitcoin address mixes upper and lower case")
+	}
+	if len(lower) > 90 {
+		return "", errors.New("bitcoin address is too long")
+	}
+	separator := strings.LastIndexByte(lower, '1')
+	hrp, data := lower[:separator], lower[separator+1:]
+	if hrp != "bc" {
+		return "", errors.New("bitcoin address is not a mainnet address")
+	}
+	if len(data) < 7 {
+		return "", errors.New("bitcoin address is too short")
+	}
+	values := make([]byte, len(data))
+	for i := range data {
+		digit := strings.IndexByte(bech32Charset, data[i])
+		if digit < 0 {
+			return "", errors.New("bitcoin address contains characters outside the bech32 alphabet")
+		}
+		values[i] = byte(digit)
+	}
+	version := values[0]
+	if version > 16 {
+		return "", errors.New("bitcoin address has an unknown witness version")
+	}
+	want := uint32(bech32Const)
+	if version > 0 {
+		want = bech32mConst
+	}
+	if bech32Polymod(append(bech32ExpandHRP(hrp), values...)) != want {
+		return "", errors.New("bitcoin address has an invalid checksum")
+	}
+	program, ok := convertBits(values[1:len(values)-6], 5, 8)
+	if !ok || len(program) < 2 || len(program) > 40 {
+		return "", errors.New("bitcoin address has an invalid witness program")
+	}
+	if version == 0 && len(program) != 20 && len(program) != 32 {
+		return "", errors.New("bitcoin address has an invalid witness program")
+	}
+	return lower, nil
+}
+
+func bech32Polymod(values []byte) uint32 {
+	generator := [5]uint32{0x3b6a57b2, 0x26508e6d, 0x1ea119fa, 0x3d4233dd, 0x2a1462b3}
+	chk := uint32(1)
+	for _, v := range values {
+		top := chk >> 25
+		chk = (chk&0x1ffffff)<<5 ^ uint32(v)
+		for i := 0; i < 5; i++ {
+			if (top>>i)&1 == 1 {
+				chk ^= generator[i]
+			}
+		}
+	}
+	return chk
+}
+
+func bech32ExpandHRP(hrp string) []byte {
+	out := make([]byte, 0, len(hrp)*2+1)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]>>5)
+	}
+	out = append(out, 0)
+	for i := 0; i < len(hrp); i++ {
+		out = append(out, hrp[i]&31)
+	}
+	return out
+}
+
+// convertBits regroups 5-bit bech32 values into bytes, rejecting non-zero
+// or oversized padding.
+func convertBits(data []byte, from, to uint) ([]byte, bool) {
+	var acc, bits uint
+	maxv := uint(1)<<to - 1
+	out := make([]byte, 0, len(data)*int(from)/int(to))
+	for _, v := range data {
+		acc = acc<<from | uint(v)
+		bits += from
+		for bits >= to {
+			bits -= to
+			out = append(out, byte(acc>>bits&maxv))
+		}
+	}
+	if bits >= from || (acc<<(to-bits))&maxv != 0 {
+		return nil, false
+	}
+	return out, true
+}
@@ -0,0 +1,10 @@
+// Package wallet validates cryptocurrency addresses and verifies wallet
+// signatures for donation addresses.
+//
+// Bitcoin addresses are checked against their base58check or bech32
+// checksums, Ethereum addresses against EIP-55, and Monero addresses by
+// shape only. Ethereum signatures made with personal_sign can be verified
+// by recovering the signer's address, which needs Keccak-256 and secp256k1
+// public-key recovery; both are implemented here because the standard
+// library offers neither.
+package wallet
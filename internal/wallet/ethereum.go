@@ -0,0 +1,123 @@
+package wallet
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+)
+
+// NormalizeEthereumAddress validates an Ethereum address and returns it in
+// its EIP-55 checksummed form. Mixed-case input must already carry a valid
+// checksum; all-lowercase or all-uppercase input carries none and is
+// accepted as is.
+func NormalizeEthereumAddress(address string) (string, error) {
+	if len(address) != 42 || (address[:2] != "0x" && address[:2] != "0X") {
+		return "", errors.New("ethereum address must be 0x followed by 40 hex digits")
+	}
+	body := address[2:]
+	if _, err := hex.DecodeString(body); err != nil {
+		return "", errors.New("ethereum address must be 0x followed by 40 hex digits")
+	}
+	checksummed := checksumEthereumAddress(strings.ToLower(body))
+	if body != strings.ToLower(body) && body != strings.ToUpper(body) && "0x"+body != checksummed {
+		return "", errors.New("ethereum address has an invalid EIP-55 checksum")
+	}
+	return checksummed, nil
+}
+
+// checksumEthereumAddress applies EIP-55 capitalization to 40 lowercase hex
+// digits: a letter is uppercased when the matching nibble of the Keccak-256
+// hash of the lowercase address is 8 or more.
+func checksumEthereumAddress(lower string) string {
+	hash := Keccak256([]byte(lower))
+	out := []byte(lower)
+	for i, c := range out {
+		if c < 'a' || c > 'f' {
+			continue
+		}
+		nibble := hash[i/2]
+		if i%2 == 0 {
+			nibble >>= 4
+		}
+		if nibble&0x0f >= 8 {
+			out[i] = c - 'a' + 'A'
+		}
+	}
+	return "0x" + string(out)
+}
+
+// PersonalMessageHash returns the EIP-191 hash wallets sign for
+// personal_sign: Keccak-256 over a fixed prefix, the message length, and the
+// message.
+func PersonalMessageHash(message string) []byte {
+	prefix := "\x19Ethereum Signed Message:\n" + strconv.Itoa(len(message))
+	return Keccak256([]byte(prefix), []byte(message))
+}
+
+// RecoverEthereumSigner returns the checksummed address whose key produced
+// signature over message with personal_sign. signature is the 65-byte
+// r || s || v value in hex, with or without a 0x prefix.
+func RecoverEthereumSigner(message, signature string) (string, error) {
+	signature = strings.TrimPrefix(strings.TrimPrefix(strings.TrimSpace(signature), "0x"), "0X")
+	raw, err := hex.DecodeString(signature)
+	if err != nil || len(raw) != 65 {
+		return "", errors.New("signature must be 65 bytes of hex")
+	}
+	v := raw[64]
+	if v >= 27 {
+		v -= 27
+	}
+	r := new(big.Int).SetBytes(raw[:32])
+	s := new(big.Int).SetBytes(raw[32:64])
+	key, err := recoverPublicKey(PersonalMessageHash(message), r, s, v)
+	if err != nil {
+		return "", fmt.Errorf("recover signer: %w", err)
+	}
+	return checksumEthereumAddress(hex.EncodeToString(Keccak256(key)[12:])), nil
+}
+
+// SignPersonalMessage signs message with personal_sign using the 32-byte
+// secp256k1 privateKey and returns the 65-byte signature in 0x-prefixed hex,
+// as a wallet would. It lets tests and tools produce signatures that
+// RecoverEthereumSigner accepts.
+func SignPersonalMessage(privateKey []byte, message string) (string, error) {
+	d := new(big.Int).SetBytes(privateKey)
+	if len(privateKey) != 32 || d.Sign() == 0 || d.Cmp(curveN) >= 0 {
+		return "", errors.New("private key must be 32 bytes within the curve order")
+	}
+	e := new(big.Int).SetBytes(PersonalMessageHash(message))
+	halfN := new(big.Int).Rsh(curveN, 1)
+	for {
+		k, err := rand.Int(rand.Reader, curveN)
+		if err != nil {
+			return "", err
+		}
+		if k.Sign() == 0 {
+			continue
+		}
+		rPoint := scalarMult(point{x: curveGx, y: curveGy}, k)
+		if rPoint.x.Cmp(curveN) >= 0 {
+			continue
+		}
+		r := new(big.Int).Set(rPoint.x)
+		s := new(big.Int).Mul(r, d)
+		s.Add(s, e).Mul(s, new(big.Int).ModInverse(k, curveN)).Mod(s, curveN)
+		if r.Sign() == 0 || s.Sign() == 0 {
+			continue
+		}
+		v := byte(rPoint.y.Bit(0))
+		if s.Cmp(halfN) > 0 {
+			s.Sub(curveN, s)
+			v ^= 1
+		}
+		raw := make([]byte, 65)
+		r.FillBytes(raw[:32])
+		s.FillBytes(raw[32:64])
+		raw[64] = 27 + v
+		return "0x" + hex.EncodeToString(raw), nil
+	}
+}
@@ -0,0 +1,98 @@
+package wallet
+
+import "math/bits"
+
+// keccakRate is the sponge rate of Keccak-256 in bytes.
+const keccakRate = 136
+
+var keccakRoundConstants = [24]uint64{
+	0x0000000000000001, 0x0000000000008082, 0x800000000000808a, 0x8000000080008000,
+	0x000000000000808b, 0x0000000080000001, 0x8000000080008081, 0x8000000000008009,
+	0x000000000000008a, 0x0000000000000088, 0x0000000080008009, 0x000000008000000a,
+	0x000000008000808b, 0x800000000000008b, 0x8000000000008089, 0x8000000000008003,
+	0x8000000000008002, 0x8000000000000080, 0x000000000000800a, 0x800000008000000a,
+	0x8000000080008081, 0x8000000000008080, 0x0000000080000001, 0x8000000080008008,
+}
+
+var keccakRotations = [25]int{
+	0, 1, 62, 28, 27,
+	36, 44, 6, 55, 20,
+	3, 10, 43, 25, 39,
+	41, 45, 15, 21, 8,
+	18, 2, 61, 56, 14,
+}
+
+// Keccak256 returns the original Keccak-256 digest of data, as used by
+// Ethereum. It differs from SHA3-256 only in its padding byte.
+func Keccak256(data ...[]byte) []byte {
+	var state [25]uint64
+	var block [keccakRate]byte
+	filled := 0
+	absorb := func() {
+		for i := 0; i < keccakRate/8; i++ {
+			state[i] ^= leUint64(block[i*8:])
+		}
+		keccakF1600(&state)
+		filled = 0
+	}
+	for _, chunk := range data {
+		for len(chunk) > 0 {
+			n := copy(block[filled:], chunk)
+			filled += n
+			chunk = chunk[n:]
+			if filled == keccakRate {
+				absorb()
+			}
+		}
+	}
+	for i := filled; i < keccakRate; i++ {
+		block[i] = 0
+	}
+	block[filled] ^= 0x01
+	block[keccakRate-1] ^= 0x80
+	absorb()
+
+	digest := make([]byte, 32)
+	for i := 0; i < 4; i++ {
+		v := state[i]
+		for j := 0; j < 8; j++ {
+			digest[i*8+j] = byte(v >> (8 * j))
+		}
+	}
+	return digest
+}
+
+func leUint64(b []byte) uint64 {
+	var v uint64
+	for i := 7; i >= 0; i-- {
+		v = v<<8 | uint64(b[i])
+	}
+	return v
+}
+
+func keccakF1600(a *[25]uint64) {
+	var c [5]uint64
+	var b [25]uint64
+	for round := 0; round < 24; round++ {
+		for x := 0; x < 5; x++ {
+			c[x] = a[x] ^ a[x+5] ^ a[x+10] ^ a[x+15] ^ a[x+20]
+		}
+		for x := 0; x < 5; x++ {
+			d := c[(x+4)%5] ^ bits.RotateLeft64(c[(x+1)%5], 1)
+			for y := 0; y < 25; y += 5 {
+				a[x+y] ^= d
+			}
+		}
+		for x := 0; x < 5; x++ {
+			for y := 0; y < 5; y++ {
+				b[y+5*((2*x+3*y)%5)] = bits.RotateLeft64(a[x+5*y], keccakRotations[x+5*y])
+			}
+		}
+		for y := 0; y < 25; y += 5 {
+			for x := 0; x < 5; x++ {
+				a[x+y] = b[x+y] ^ (^b[(x+1)%5+y] & b[(x+2)%5+y])
+			}
+		}
+		a[0] ^= keccakRoundConstants[round]
+	}
+}
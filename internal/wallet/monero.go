@@ -0,0 +1,36 @@
+package wallet
+
+import (
+	"errors"
+	"strings"
+)
+
+// Monero address lengths in base58 characters.
+const (
+	moneroStandardLength   = 95
+	moneroIntegratedLength = 106
+)
+
+// NormalizeMoneroAddress checks the shape of a mainnet Monero address:
+// standard and integrated addresses start with 4, subaddresses with 8. The
+// checksum is not verified.
+func NormalizeMoneroAddress(address string) (string, error) {
+	switch len(address) {
+	case moneroStandardLength:
+		if address[0] != '4' && address[0] != '8' {
+			return "", errors.New("monero address must start with 4 or 8")
+		}
+	case moneroIntegratedLength:
+		if address[0] != '4' {
+			return "", errors.New("monero integrated address must start with 4")
+		}
+	default:
+		return "", errors.New("monero address must be 95 or 106 characters")
+	}
+	for _, c := range address {
+		if !strings.ContainsRune(base58Alphabet, c) {
+			return "", errors.New("address contains characters outside the base58 alphabet")
+		}
+	}
+	return address, nil
+}
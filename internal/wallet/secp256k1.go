@@ -0,0 +1,105 @@
+package wallet
+
+import (
+	"errors"
+	"math/big"
+)
+
+// secp256k1 domain parameters.
+var (
+	curveP, _  = new(big.Int).SetString("fffffffffffffffffffffffffffffffffffffffffffffffffffffffefffffc2f", 16)
+	curveN, _  = new(big.Int).SetString("fffffffffffffffffffffffffffffffebaaedce6af48a03bbfd25e8cd0364141", 16)
+	curveGx, _ = new(big.Int).SetString("79be667ef9dcbbac55a06295ce870b07029bfcdb2dce28d959f2815b16f81798", 16)
+	curveGy, _ = new(big.Int).SetString("483ada7726a3c4655da4fbfc0e1108a8fd17b448a68554199c47d08ffb10d4b8", 16)
+	curveB     = big.NewInt(7)
+)
+
+// point is an affine point on secp256k1; nil coordinates mark infinity.
+type point struct {
+	x, y *big.Int
+}
+
+func (p point) infinity() bool { return p.x == nil }
+
+func addPoints(a, b point) point {
+	if a.infinity() {
+		return b
+	}
+	if b.infinity() {
+		return a
+	}
+	var slope *big.Int
+	if a.x.Cmp(b.x) == 0 {
+		if a.y.Cmp(b.y) != 0 || a.y.Sign() == 0 {
+			return point{}
+		}
+		// Doubling: slope = 3x² / 2y.
+		num := new(big.Int).Mul(a.x, a.x)
+		num.Mul(num, big.NewInt(3))
+		den := new(big.Int).Lsh(a.y, 1)
+		slope = num.Mul(num, den.ModInverse(den, curveP))
+	} else {
+		num := new(big.Int).Sub(b.y, a.y)
+		den := new(big.Int).Sub(b.x, a.x)
+		den.Mod(den, curveP)
+		slope = num.Mul(num, den.ModInverse(den, curveP))
+	}
+	slope.Mod(slope, curveP)
+	x := new(big.Int).Mul(slope, slope)
+	x.Sub(x, a.x).Sub(x, b.x).Mod(x, curveP)
+	y := new(big.Int).Sub(a.x, x)
+	y.Mul(y, slope).Sub(y, a.y).Mod(y, curveP)
+	return point{x: x, y: y}
+}
+
+func scalarMult(p point, k *big.Int) point {
+	result := point{}
+	for i := k.BitLen() - 1; i >= 0; i-- {
+		result = addPoints(result, result)
+		if k.Bit(i) == 1 {
+			result = addPoints(result, p)
+		}
+	}
+	return result
+}
+
+// recoverPublicKey returns the uncompressed public key, without its 0x04
+// prefix, whose signature (r, s) with recovery id v covers hash.
+func recoverPublicKey(hash []byte, r, s *big.Int, v byte) ([]byte, error) {
+	if r.Sign() <= 0 || r.Cmp(curveN) >= 0 || s.Sign() <= 0 || s.Cmp(curveN) >= 0 {
+		return nil, errors.New("signature values out of range")
+	}
+	if v > 1 {
+		return nil, errors.New("unsupported recovery id")
+	}
+	// R.x = r; recovery ids 2 and 3 (r >= n) never occur in practice.
+	y2 := new(big.Int).Exp(r, big.NewInt(3), curveP)
+	y2.Add(y2, curveB).Mod(y2, curveP)
+	exp := new(big.Int).Add(curveP, big.NewInt(1))
+	exp.Rsh(exp, 2)
+	y := new(big.Int).Exp(y2, exp, curveP)
+	if new(big.Int).Exp(y, big.NewInt(2), curveP).Cmp(y2) != 0 {
+		return nil, errors.New("signature point is not on the curve")
+	}
+	if y.Bit(0) != uint(v) {
+		y.Sub(curveP, y)
+	}
+	rPoint := point{x: new(big.Int).Set(r), y: y}
+
+	e := new(big.Int).SetBytes(hash)
+	e.Mod(e, curveN)
+	rInv := new(big.Int).ModInverse(r, curveN)
+	u1 := new(big.Int).Neg(e)
+	u1.Mul(u1, rInv).Mod(u1, curveN)
+	u2 := new(big.Int).Mul(s, rInv)
+	u2.Mod(u2, curveN)
+
+	q := addPoints(scalarMult(point{x: curveGx, y: curveGy}, u1), scalarMult(rPoint, u2))
+	if q.infinity() {
+		return nil, errors.New("signature recovers the point at infinity")
+	}
+	key := make([]byte, 64)
+	q.x.FillBytes(key[:32])
+	q.y.FillBytes(key[32:])
+	return key, nil
+}
@@ -0,0 +1,140 @@
+package wallet
+
+import (
+	"encoding/hex"
+	"strings"
+	"testing"
+)
+
+func TestKeccak256(t *testing.T) {
+	cases := map[string]string{
+		"":    "c5d2460186f7233c927e7db2dcc703c0e500b653ca82273b7bfad8045d85a470",
+		"abc": "4e03657aea45a94fc7d47ba826c8d667c0d1e6e33a64a036ec44f58fa12d6c45",
+	}
+	for input, want := range cases {
+		if got := hex.EncodeToString(Keccak256([]byte(input))); got != want {
+			t.Fatalf("Keccak256(%q) = %s, want %s", input, got, want)
+		}
+	}
+
+	long := []byte(strings.Repeat("a", 3*keccakRate+7))
+	whole := Keccak256(long)
+	split := Keccak256(long[:keccakRate-1], long[keccakRate-1:])
+	if hex.EncodeToString(whole) != hex.EncodeToString(split) {
+		t.Fatal("expected split input to hash like the whole input")
+	}
+}
+
+func TestNormalizeEthereumAddress(t *testing.T) {
+	checksummed := []string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAed",
+		"0xfB6916095ca1df60bB79Ce92cE3Ea74c37c5d359",
+		"0xdbF03B407c01E7cD3CBea99509d93f8DDDC8C6FB",
+		"0xD1220A0cf47c7B9Be7A2E6BA89F429762e7b9aDb",
+	}
+	for _, want := range checksummed {
+		for _, input := range []string{want, strings.ToLower(want), "0x" + strings.ToUpper(want[2:])} {
+			got, err := NormalizeEthereumAddress(input)
+			if err != nil {
+				t.Fatalf("NormalizeEthereumAddress(%q): %v", input, err)
+			}
+			if got != want {
+				t.Fatalf("NormalizeEthereumAddress(%q) = %q, want %q", input, got, want)
+			}
+		}
+	}
+
+	for _, input := range []string{
+		"0x5aAeb6053F3E94C9b9A09f33669435E7Ef1BeAeD",
+		"0x5aaeb6053f3e94c9b9a09f33669435e7ef1bea",
+		"5aaeb6053f3e94c9b9a09f33669435e7ef1beaed00",
+		"0x5aaeb6053f3e94c9b9a09f33669435e7ef1beaeg",
+	} {
+		if _, err := NormalizeEthereumAddress(input); err == nil {
+			t.Fatalf("expected NormalizeEthereumAddress(%q) to fail", input)
+		}
+	}
+}
+
+func TestRecoverEthereumSigner(t *testing.T) {
+	// Test vector from the web3.js accounts documentation.
+	const signature = "0xb91467e570a6466aa9e9876cbcd013baba02900b8979d43fe208a4a4f339f5fd6007e74cd82e037b800186422fc2da167c747ef045e5d18a5f5d4300f8e1a0291c"
+	signer, err := RecoverEthereumSigner("Some data", signature)
+	if err != nil {
+		t.Fatalf("RecoverEthereumSigner: %v", err)
+	}
+	if signer != "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23" {
+		t.Fatalf("unexpected signer %s", signer)
+	}
+
+	other, err := RecoverEthereumSigner("Other data", signature)
+	if err == nil && other == signer {
+		t.Fatal("expected a different message to recover a different signer")
+	}
+	if _, err := RecoverEthereumSigner("Some data", signature[:len(signature)-2]); err == nil {
+		t.Fatal("expected a truncated signature to fail")
+	}
+}
+
+func TestSignPersonalMessageRoundTrip(t *testing.T) {
+	key, _ := hex.DecodeString("4c0883a69102937d6231471b5dbb6204fe5129617082792ae468d01a3f362318")
+	signature, err := SignPersonalMessage(key, "Some data")
+	if err != nil {
+		t.Fatalf("SignPersonalMessage: %v", err)
+	}
+	signer, err := RecoverEthereumSigner("Some data", signature)
+	if err != nil {
+		t.Fatalf("RecoverEthereumSigner: %v", err)
+	}
+	if signer != "0x2c7536E3605D9C16a7a3D7b1898e529396a65c23" {
+		t.Fatalf("unexpected signer %s", signer)
+	}
+}
+
+func TestNormalizeBitcoinAddress(t *testing.T) {
+	valid := map[string]string{
+		"1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa":                             "1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNa",
+		"3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy":                             "3J98t1WpEZ73CNmQviecrnyiWrnqRhWNLy",
+		"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4":                     "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+		"BC1QW508D6QEJXTDG4Y5R3ZARVARY0C5XW7KV8F3T4":                     "bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",
+		"bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0": "bc1p0xlxvlhemja6c4dqv22uapctqupfhlxm9h8z3k2e72q4k9hcz7vqzk5jj0",
+	}
+	for input, want := range valid {
+		got, err := NormalizeBitcoinAddress(input)
+		if err != nil {
+			t.Fatalf("NormalizeBitcoinAddress(%q): %v", input, err)
+		}
+		if got != want {
+			t.Fatalf("NormalizeBitcoinAddress(%q) = %q, want %q", input, got, want)
+		}
+	}
+
+	for _, input := range []string{
+		"1A1zP1eP5QGefi2DMPTfTL5SLmv7DivfNb",                                         // base58check checksum
+		"1A1zP1eP5QGefi2DMPTfTL5SLmv7Divf0a",                                         // outside base58
+		"bc1qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t5",                                 // bech32 checksum
+		"bc1Qw508d6qejxtdg4y5r3zarvary0c5xw7kv8f3t4",                                 // mixed case
+		"tb1qw508d6qejxtdg4y5r3zarvary0c5xdyss8ak",                                   // testnet
+		"bc1pw508d6qejxtdg4y5r3zarvary0c5xw7kw508d6qejxtdg4y5r3zarvary0c5xw7k7grplx", // bech32 rather than bech32m on v1
+	} {
+		if _, err := NormalizeBitcoinAddress(input); err == nil {
+			t.Fatalf("expected NormalizeBitcoinAddress(%q) to fail", input)
+		}
+	}
+}
+
+func TestNormalizeMoneroAddress(t *testing.T) {
+	const standard = "44AFFq5kSiGBoZ4NMDwYtN18obc8AemS33DBLWs3H7otXft3XjrpDtQGv7SqSsaBYBb98uNbr2VBBEt7f2wfn3RVGQBEP3A"
+	if _, err := NormalizeMoneroAddress(standard); err != nil {
+		t.Fatalf("NormalizeMoneroAddress: %v", err)
+	}
+	for _, input := range []string{
+		standard[:94],
+		"1" + standard[1:],
+		standard[:94] + "0",
+	} {
+		if _, err := NormalizeMoneroAddress(input); err == nil {
+			t.Fatalf("expected NormalizeMoneroAddress(%q) to fail", input)
+		}
+	}
+}
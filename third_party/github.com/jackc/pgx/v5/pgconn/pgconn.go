@@ -7,3 +7,24 @@ type CommandTag struct {
 func (c CommandTag) RowsAffected() int64 {
 	return c.rowsAffected
 }
+
+// PgError represents an error reported by the PostgreSQL server. See
+// http://www.postgresql.org/docs/current/static/protocol-error-fields.html for
+// detailed field description.
+type PgError struct {
+	Severity       string
+	Code           string
+	Message        string
+	Detail         string
+	Hint           string
+	ConstraintName string
+}
+
+func (pe *PgError) Error() string {
+	return pe.Severity + ": " + pe.Message + " (SQLSTATE " + pe.Code + ")"
+}
+
+// SQLState returns the SQLState of the error.
+func (pe *PgError) SQLState() string {
+	return pe.Code
+}
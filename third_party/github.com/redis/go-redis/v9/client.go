@@ -51,6 +51,7 @@ type Options struct {
 type UniversalClient interface {
 	Close() error
 	Do(ctx context.Context, args ...interface{}) (interface{}, error)
+	Pipeline(ctx context.Context, cmds ...[]interface{}) ([]interface{}, error)
 }
 
 type Client struct {
@@ -182,6 +183,60 @@ func (c *Client) Do(ctx context.Context, args ...interface{}) (interface{}, erro
 	return nil, errors.New("redis: command failed")
 }
 
+// Pipeline writes every command on a single connection before reading any
+// reply, so the batch costs one network round trip. Replies are returned in
+// command order; the first error reply is returned after all replies have
+// been drained so the connection stays usable.
+func (c *Client) Pipeline(ctx context.Context, cmds ...[]interface{}) ([]interface{}, error) {
+	if len(cmds) == 0 {
+		return nil, errors.New("redis: missing command")
+	}
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	var lastErr error
+	maxRetries := c.opt.MaxRetries
+	if maxRetries < 0 {
+		maxRetries = 0
+	}
+	for attempt := 0; attempt <= maxRetries; attempt++ {
+		if attempt > 0 {
+			sleep := backoffDuration(attempt, c.opt.MinRetryBackoff, c.opt.MaxRetryBackoff)
+			if sleep > 0 {
+				timer := time.NewTimer(sleep)
+				select {
+				case <-ctx.Done():
+					timer.Stop()
+					return nil, ctx.Err()
+				case <-timer.C:
+				}
+			}
+		}
+		conn, err := c.pool.get(ctx)
+		if err != nil {
+			lastErr = err
+			if ctx.Err() != nil {
+				return nil, ctx.Err()
+			}
+			continue
+		}
+		replies, replyErr, err := conn.pipeline(ctx, cmds)
+		c.pool.put(conn, err == nil)
+		if err == nil {
+			return replies, replyErr
+		}
+		if errors.Is(err, io.EOF) || isNetworkError(err) {
+			lastErr = err
+			continue
+		}
+		return nil, err
+	}
+	if lastErr != nil {
+		return nil, lastErr
+	}
+	return nil, errors.New("redis: pipeline failed")
+}
+
 type connPool struct {
 	dial         func(ctx context.Context) (*conn, error)
 	conns        chan *conn
@@ -310,6 +365,31 @@ func (c *conn) do(ctx context.Context, args ...interface{}) (interface{}, error)
 	return c.read(ctx)
 }
 
+// pipeline returns the replies, the first error reply, and any transport
+// error. Only transport errors leave the connection in an unknown state.
+func (c *conn) pipeline(ctx context.Context, cmds [][]interface{}) ([]interface{}, error, error) {
+	for _, args := range cmds {
+		if err := c.write(ctx, args...); err != nil {
+			return nil, nil, err
+		}
+	}
+	replies := make([]interface{}, 0, len(cmds))
+	var replyErr error
+	for range cmds {
+		reply, err := c.read(ctx)
+		if err != nil {
+			if c.netConn == nil || isNetworkError(err) {
+				return nil, nil, err
+			}
+			if replyErr == nil {
+				replyErr = err
+			}
+		}
+		replies = append(replies, reply)
+	}
+	return replies, replyErr, nil
+}
+
 func (c *conn) write(ctx context.Context, args ...interface{}) error {
 	if c.netConn == nil {
 		return errors.New("redis: connection closed")
@@ -16,6 +16,7 @@ const state = {
     profileIndex: new Map(),
     selectedProfileId: null,
     currentUser: null,
+    impersonation: null,
     chatClient: null,
     moderation: { queue: [], actions: [] },
     analytics: { summary: null, perChannel: [] },
@@ -79,6 +80,14 @@ function initChatClient() {
         onOpen: () => {
             syncChatSubscriptions();
         },
+        onResync: (channelId) => {
+            loadChatHistory(channelId, 50)
+                .then(() => {
+                    renderChat();
+                    renderDashboard();
+                })
+                .catch((error) => showToast(`Chat reload failed: ${error.message}`, "error"));
+        },
     });
     return state.chatClient;
 }
@@ -124,10 +133,25 @@ function handleChatEvent(event) {
         }
         return;
     }
+    if (event.type === "moderation" && event.moderation?.action === "delete_message") {
+        const messages = state.chat[event.moderation.channelId];
+        if (messages) {
+            state.chat[event.moderation.channelId] = messages.filter((item) => item.id !== event.moderation.messageId);
+            renderChat();
+            renderDashboard();
+        }
+        return;
+    }
     if (event.type === "moderation" && event.moderation) {
         const action = event.moderation.action.replace(/_/g, " ");
         const target = event.moderation.targetId;
         showToast(`Moderation ${action} for ${target}`, "info");
+        return;
+    }
+    if (event.type === "moderator" && event.moderator) {
+        const { action, userId, reason } = event.moderator;
+        const detail = action === "removed" && reason === "expired" ? " (grant expired)" : "";
+        showToast(`Moderator ${action}: ${userId}${detail}`, "info");
     }
 }
 
@@ -339,7 +363,9 @@ function renderAccountStatus() {
     if (state.currentUser) {
         accountActions.hidden = false;
         if (accountName) {
-            accountName.textContent = `Signed in as ${state.currentUser.displayName}`;
+            accountName.textContent = state.impersonation
+                ? `Viewing as ${state.currentUser.displayName} (impersonated by ${state.impersonation.adminDisplayName})`
+                : `Signed in as ${state.currentUser.displayName}`;
         }
     } else {
         accountActions.hidden = true;
@@ -350,6 +376,16 @@ function renderAccountStatus() {
 }
 
 async function handleSignOut() {
+    if (state.impersonation) {
+        // Ending an impersonation returns to the admin's own session.
+        try {
+            await apiRequest("/api/auth/session", { method: "DELETE" });
+        } catch (error) {
+            console.warn("Failed to end impersonation", error);
+        }
+        window.location.reload();
+        return;
+    }
     try {
         await apiRequest("/api/auth/session", { method: "DELETE" });
     } catch (error) {
@@ -765,30 +801,50 @@ function renderChannels() {
             createElement("span", { className: liveClass, textContent: channel.liveState }),
         );
         channelMeta.appendChild(stateIndicator);
+        if (channel.recordingPolicy) {
+            channelMeta.appendChild(
+                createElement("span", {
+                    className: "card__meta",
+                    textContent: `Recordings: ${channel.recordingPolicy}`,
+                }),
+            );
+        }
         card.appendChild(channelMeta);
 
-        if (channel.streamKey) {
+        if (channel.streamKey || channel.streamKeyHint) {
             const details = document.createElement("details");
             const summary = createElement("summary", { textContent: "Stream key & ingest tips" });
             details.appendChild(summary);
             const streamKey = createElement("div", { className: "stream-key" });
-            const copyButton = createElement("button", {
-                className: "secondary",
-                textContent: "Copy",
-            });
-            copyButton.addEventListener("click", async () => {
-                try {
-                    await navigator.clipboard.writeText(channel.streamKey);
-                    showToast("Stream key copied");
-                } catch (error) {
-                    showToast("Clipboard not available", "error");
-                }
-            });
-            streamKey.append(
-                createElement("code", { textContent: channel.streamKey }),
-                copyButton,
-            );
+            if (channel.streamKey) {
+                const copyButton = createElement("button", {
+                    className: "secondary",
+                    textContent: "Copy",
+                });
+                copyButton.addEventListener("click", async () => {
+                    try {
+                        await navigator.clipboard.writeText(channel.streamKey);
+                        showToast("Stream key copied");
+                    } catch (error) {
+                        showToast("Clipboard not available", "error");
+                    }
+                });
+                streamKey.append(
+                    createElement("code", { textContent: channel.streamKey }),
+                    copyButton,
+                );
+            } else {
+                streamKey.append(createElement("code", { textContent: channel.streamKeyHint }));
+            }
             details.appendChild(streamKey);
+            details.appendChild(
+                createElement("p", {
+                    className: "card__meta",
+                    textContent:
+                        channel.streamKeyNotice ||
+                        "Only a hash of the stream key is stored. Rotate the key if you need to copy it again.",
+                }),
+            );
             const ingest = createElement("p", { className: "card__meta" });
             ingest.append(
                 "Use ",
@@ -1823,7 +1879,7 @@ function renderStreamControls() {
         const title = createElement("h3", { textContent: channel.title });
         const key = createElement("span", {
             className: "card__meta",
-            textContent: channel.streamKey || "Stream key unavailable",
+            textContent: channel.streamKey || channel.streamKeyHint || "Stream key unavailable",
         });
         header.append(title, key);
         card.appendChild(header);
@@ -2169,15 +2225,24 @@ async function loadModeration(force = false) {
     }
 }
 
-async function resolveModerationFlag(flagId, resolution) {
+const moderationActionToasts = {
+    unpublish_recording: "Recording unpublished",
+    stop_stream: "Stream stopped",
+};
+
+async function resolveModerationFlag(flagId, resolution, action = "") {
     if (!flagId) {
         return;
     }
+    const body = { resolution };
+    if (action) {
+        body.action = action;
+    }
     await apiRequest(`/api/moderation/queue/${flagId}`, {
         method: "POST",
-        body: JSON.stringify({ resolution }),
+        body: JSON.stringify(body),
     });
-    showToast(resolution === "ban" ? "Viewer banned" : "Flag dismissed");
+    showToast(moderationActionToasts[action] || (resolution === "ban" ? "Viewer banned" : "Flag dismissed"));
     moderationLoaded = false;
     await loadModeration(true);
 }
@@ -2219,6 +2284,22 @@ function renderModeration() {
                 }),
             );
 
+            const subject = (flag.subjectType || "chat_message").replace(/_/g, " ");
+            card.appendChild(
+                createElement("p", {
+                    className: "card__meta",
+                    textContent: `Subject: ${subject} · Category: ${flag.category || "other"}`,
+                }),
+            );
+            if (flag.claimant) {
+                card.appendChild(
+                    createElement("p", {
+                        className: "card__meta",
+                        textContent: `Claimant: ${flag.claimant.name} <${flag.claimant.email}>`,
+                    }),
+                );
+            }
+
             if (flag.message) {
                 card.appendChild(
                     createElement("blockquote", {
@@ -2248,6 +2329,23 @@ function renderModeration() {
                     dataset: { action: "resolve-flag", id: flag.id, resolution: "ban" },
                 }),
             );
+            if (flag.subjectType === "recording") {
+                actions.appendChild(
+                    createElement("button", {
+                        className: "danger",
+                        textContent: "Unpublish",
+                        dataset: { action: "resolve-flag", id: flag.id, resolution: "unpublished", reportAction: "unpublish_recording" },
+                    }),
+                );
+            } else if (flag.subjectType === "stream_session" || flag.subjectType === "channel") {
+                actions.appendChild(
+                    createElement("button", {
+                        className: "danger",
+                        textContent: "Stop stream",
+                        dataset: { action: "resolve-flag", id: flag.id, resolution: "stream_stopped", reportAction: "stop_stream" },
+                    }),
+                );
+            }
             card.appendChild(actions);
 
             queueContainer.appendChild(card);
@@ -2255,9 +2353,9 @@ function renderModeration() {
 
         queueContainer.querySelectorAll("[data-action=resolve-flag]").forEach((button) => {
             button.addEventListener("click", async () => {
-                const { id, resolution } = button.dataset;
+                const { id, resolution, reportAction } = button.dataset;
                 try {
-                    await resolveModerationFlag(id, resolution);
+                    await resolveModerationFlag(id, resolution, reportAction);
                 } catch (error) {
                     showToast(error.message, "error");
                 }
@@ -2448,6 +2546,7 @@ function attachActions() {
 async function initialize() {
     const session = await requireSession();
     state.currentUser = session.user;
+    state.impersonation = session.impersonation || null;
     initChatClient();
     renderAccountStatus();
     attachActions();
@@ -1,12 +1,32 @@
+// newClientMessageId returns a UUID the gateway uses to deduplicate retried
+// messages, or undefined where the browser cannot generate one.
+function newClientMessageId() {
+    if (typeof crypto !== "undefined" && typeof crypto.randomUUID === "function") {
+        return crypto.randomUUID();
+    }
+    return undefined;
+}
+
+// eventChannel returns the channel a sequenced chat event belongs to.
+function eventChannel(event) {
+    return event?.message?.channelId || event?.moderation?.channelId;
+}
+
+// ChatClient resumes each joined channel after a reconnect from the last
+// sequence number it saw, so missed events are replayed through onEvent.
+// When too much was missed, onResync(channelId) asks the caller to reload
+// the chat history instead.
 export class ChatClient {
-    constructor({ url = "/api/chat/ws", onEvent, onError, onOpen } = {}) {
+    constructor({ url = "/api/chat/ws", onEvent, onError, onOpen, onResync } = {}) {
         this.url = this.resolveURL(url);
         this.onEvent = onEvent;
         this.onError = onError;
         this.onOpen = onOpen;
+        this.onResync = onResync;
         this.socket = null;
         this.pendingJoins = new Set();
         this.joined = new Set();
+        this.lastSeq = new Map();
         this.queue = [];
         this.reconnectDelay = 500;
         this.maxDelay = 4000;
@@ -75,13 +95,23 @@ export class ChatClient {
         }
     }
 
-    join(channelId) {
+    // join subscribes to the channel. lastSeq, such as the highest seq in
+    // history loaded over REST, resumes from that point; rejoins after a
+    // reconnect resume from the last event received.
+    join(channelId, lastSeq) {
         if (!channelId) {
             return;
         }
+        if (typeof lastSeq === "number") {
+            this.lastSeq.set(channelId, lastSeq);
+        }
         this.pendingJoins.add(channelId);
         this.joined.add(channelId);
-        this.send({ type: "join", channelId });
+        const payload = { type: "join", channelId };
+        if (this.lastSeq.has(channelId)) {
+            payload.lastSeq = this.lastSeq.get(channelId);
+        }
+        this.send(payload);
     }
 
     leave(channelId) {
@@ -90,11 +120,17 @@ export class ChatClient {
         }
         this.pendingJoins.delete(channelId);
         this.joined.delete(channelId);
+        this.lastSeq.delete(channelId);
         this.send({ type: "leave", channelId });
     }
 
-    message(channelId, content) {
-        this.send({ type: "message", channelId, content });
+    message(channelId, content, clientMessageId = newClientMessageId()) {
+        const payload = { type: "message", channelId, content };
+        if (clientMessageId) {
+            payload.clientMessageId = clientMessageId;
+        }
+        this.send(payload);
+        return clientMessageId;
     }
 
     timeout(channelId, targetId, durationMs) {
@@ -121,13 +157,41 @@ export class ChatClient {
             console.warn("Invalid chat payload", error);
             return;
         }
-        if (payload?.type === "event" && typeof this.onEvent === "function") {
-            this.onEvent(payload.event);
+        if (payload?.type === "event") {
+            this.deliver(payload.event);
+            return;
+        }
+        if (payload?.type === "backfill") {
+            if (payload.overflow) {
+                this.lastSeq.set(payload.channelId, payload.lastSeq);
+                if (typeof this.onResync === "function") {
+                    this.onResync(payload.channelId);
+                }
+                return;
+            }
+            for (const missed of payload.events || []) {
+                this.deliver(missed);
+            }
+            this.lastSeq.set(payload.channelId, payload.lastSeq);
             return;
         }
         if (payload?.type === "error" && typeof this.onError === "function") {
-            this.onError(new Error(payload.error));
+            const error = new Error(payload.error);
+            if (payload.code) {
+                error.code = payload.code;
+            }
+            this.onError(error);
             return;
         }
     }
+
+    deliver(event) {
+        const channelId = eventChannel(event);
+        if (event?.seq && channelId && this.joined.has(channelId)) {
+            this.lastSeq.set(channelId, Math.max(this.lastSeq.get(channelId) || 0, event.seq));
+        }
+        if (typeof this.onEvent === "function") {
+            this.onEvent(event);
+        }
+    }
 }
@@ -10,6 +10,7 @@ import {
   StreamSession,
   fetchChannelSessions,
   fetchManagedChannels,
+  rotateStreamKey,
   updateChannel,
 } from "../../../../lib/viewer-api";
 
@@ -43,7 +44,8 @@ export default function CreatorLivePage() {
   const [savingTitle, setSavingTitle] = useState(false);
   const [titleError, setTitleError] = useState<string | undefined>();
   const [titleSaved, setTitleSaved] = useState(false);
-  const [streamKeyVisible, setStreamKeyVisible] = useState(false);
+  const [revealedStreamKey, setRevealedStreamKey] = useState<string | undefined>();
+  const [rotatingKey, setRotatingKey] = useState(false);
   const [copyMessage, setCopyMessage] = useState<string | undefined>();
   const router = useRouter();
 
@@ -103,7 +105,7 @@ export default function CreatorLivePage() {
   }, [playback?.channel.title]);
 
   useEffect(() => {
-    setStreamKeyVisible(false);
+    setRevealedStreamKey(undefined);
     setCopyMessage(undefined);
   }, [channelId, managedChannel?.id]);
 
@@ -180,12 +182,33 @@ export default function CreatorLivePage() {
     );
   }
 
+  const handleRotateKey = async () => {
+    if (!managedChannel || !isChannelOwner) {
+      return;
+    }
+    if (!window.confirm("Rotate the stream key? Encoders using the current key will be disconnected on their next publish.")) {
+      return;
+    }
+    setRotatingKey(true);
+    setCopyMessage(undefined);
+    try {
+      const updated = await rotateStreamKey(managedChannel.id);
+      setRevealedStreamKey(updated.streamKey);
+      setManagedChannel({ ...managedChannel, streamKeyHint: updated.streamKeyHint, streamKeyNotice: updated.streamKeyNotice });
+    } catch (err) {
+      const message = err instanceof Error ? err.message : "Unable to rotate stream key";
+      setCopyMessage(message);
+    } finally {
+      setRotatingKey(false);
+    }
+  };
+
   const handleCopyKey = async () => {
-    if (!streamKeyVisible || !managedChannel?.streamKey || !isChannelOwner) {
+    if (!revealedStreamKey || !isChannelOwner) {
       return;
     }
     try {
-      await navigator.clipboard.writeText(managedChannel.streamKey);
+      await navigator.clipboard.writeText(revealedStreamKey);
       setCopyMessage("Copied");
     } catch (err) {
       const message = err instanceof Error ? err.message : "Unable to copy";
@@ -277,18 +300,23 @@ export default function CreatorLivePage() {
                 ) : isChannelOwner ? (
                   <div className="stack" style={{ gap: "0.5rem" }}>
                     <div style={codeBlockStyle} aria-live="polite">
-                      {streamKeyVisible ? managedChannel?.streamKey : "Hidden"}
+                      {revealedStreamKey ?? managedChannel?.streamKeyHint ?? "Hidden"}
                     </div>
+                    <p className="muted">
+                      {revealedStreamKey
+                        ? managedChannel?.streamKeyNotice ?? "Copy this stream key now; it cannot be shown again."
+                        : "Only a hash of the stream key is stored. Rotate the key to get a new one to copy."}
+                    </p>
                     <div className="cluster" style={{ gap: "0.5rem", flexWrap: "wrap" }}>
                       <button
                         type="button"
                         className="secondary-button"
                         onClick={() => {
-                          setStreamKeyVisible((prev) => !prev);
-                          setCopyMessage(undefined);
+                          void handleRotateKey();
                         }}
+                        disabled={rotatingKey}
                       >
-                        {streamKeyVisible ? "Hide" : "Show"}
+                        {rotatingKey ? "Rotating…" : "Rotate key"}
                       </button>
                       <button
                         type="button"
@@ -296,7 +324,7 @@ export default function CreatorLivePage() {
                         onClick={() => {
                           void handleCopyKey();
                         }}
-                        disabled={!streamKeyVisible}
+                        disabled={!revealedStreamKey}
                       >
                         Copy
                       </button>
@@ -136,7 +136,13 @@ export function ChannelHeader({ data, onFollowChange, onSubscriptionChange }: Ch
       <header className="channel-hero__top">
         <div className="channel-hero__identity">
           <div className="channel-hero__eyebrow">
-            {data.live ? <span className="pill pill--live">Live</span> : <span className="pill">Offline</span>}
+            {data.live ? (
+              <span className="pill pill--live">
+                {data.streamState === "starting" ? "Starting soon" : data.streamState === "interrupted" ? "Reconnecting" : "Live"}
+              </span>
+            ) : (
+              <span className="pill">Offline</span>
+            )}
             {viewerCount !== undefined && (
               <span className="pill pill--ghost" aria-label="Current viewers">
                 <svg viewBox="0 0 20 20" aria-hidden="true" focusable="false">
@@ -17,15 +17,16 @@ export function Player({ playback }: { playback?: Playback }) {
       const setup = async () => {
         const mod = await import("ovenplayer");
         const OvenPlayer = mod.default ?? (mod as any);
+        // OvenPlayer falls back through the sources in order, so WebRTC is
+        // tried first, then LL-HLS, then plain HLS.
+        const sources = playback.endpoints?.length
+          ? playback.endpoints.map((endpoint) => ({ type: endpoint.protocol, file: endpoint.url }))
+          : [{ type: "webrtc", file: playback.playbackUrl }];
         instance = OvenPlayer.create(playerId, {
           autoStart: true,
+          autoFallback: true,
           mute: false,
-          sources: [
-            {
-              type: "webrtc",
-              file: playback.playbackUrl
-            }
-          ]
+          sources
         });
       };
       void setup();
@@ -437,6 +437,20 @@ export function UploadManager({ channelId, ownerId }: UploadManagerProps) {
                 <p className="muted">
                   {item.status.replace(/_/g, " ")} · {item.progress}% · {Math.round(item.sizeBytes / 1_000_000)} MB
                 </p>
+                {item.steps && item.steps.length > 0 && (
+                  <ol className="upload-steps">
+                    {item.steps.map((step) => (
+                      <li
+                        key={step.name}
+                        className={`upload-steps__item upload-steps__item--${step.status}`}
+                        aria-current={step.name === item.failedStep ? "step" : undefined}
+                      >
+                        <span className="upload-steps__name">{step.name}</span>
+                        <span className="muted">{step.detail || step.status}</span>
+                      </li>
+                    ))}
+                  </ol>
+                )}
                 {item.error && <p className="error">{item.error}</p>}
                 <div className="upload-card__actions">
                   <button type="button" className="secondary-button" onClick={() => handleDelete(item.id)}>
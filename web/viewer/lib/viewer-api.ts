@@ -1,6 +1,9 @@
 export type ChannelPublic = {
   id: string;
   ownerId: string;
+  // slug addresses the channel in shareable URLs; see fetchChannelBySlug.
+  // Servers from before slugs leave it out.
+  slug?: string;
   title: string;
   category?: string;
   tags: string[];
@@ -8,11 +11,44 @@ export type ChannelPublic = {
   currentSessionId?: string;
   createdAt: string;
   updatedAt: string;
+  playbackRestriction?: "followers" | "subscribers";
+  // matureContent channels should have their thumbnails blurred.
+  matureContent?: boolean;
+  // language is a BCP-47 tag; absent until the creator sets one.
+  language?: string;
+  contentWarnings?: ContentWarning[];
 };
 
+export type ContentWarning =
+  | "violence"
+  | "gambling"
+  | "strong_language"
+  | "sexual_themes"
+  | "drugs"
+  | "flashing_lights";
+
+// DirectoryFilters narrow a directory search. Channels without a language
+// are left out while language is set.
+export type DirectoryFilters = {
+  language?: string;
+  excludeWarnings?: ContentWarning[];
+};
+
+export type PlaybackWithheldReason = "age_confirmation_required" | "age_restricted" | "playback_restricted";
+
+// StreamState separates a provisioned stream still waiting for media
+// ("starting") and one whose encoder dropped out ("interrupted") from "live".
+export type StreamState = "offline" | "starting" | "live" | "interrupted";
+
 export type ManagedChannel = ChannelPublic & {
-  streamKey: string;
+  // streamKey is only present on the response to a key rotation; the server
+  // keeps a hash and exposes streamKeyHint everywhere else.
+  streamKey?: string;
+  streamKeyHint?: string;
+  streamKeyNotice?: string;
   ingestEndpoints?: string[];
+  playbackPreviews?: boolean;
+  recordingPolicy?: "manual" | "auto-publish" | "discard";
 };
 
 export type RenditionManifest = {
@@ -79,6 +115,8 @@ export type CryptoAddress = {
   currency: string;
   address: string;
   note?: string;
+  verified?: boolean;
+  verifiedAt?: string;
 };
 
 export type TipResponse = {
@@ -136,6 +174,12 @@ export type Rendition = {
   bitrate?: number;
 };
 
+export type PlaybackEndpoint = {
+  protocol: "webrtc" | "llhls" | "hls" | string;
+  url: string;
+  priority: number;
+};
+
 export type Playback = {
   sessionId: string;
   startedAt: string;
@@ -145,6 +189,13 @@ export type Playback = {
   playerHint?: string;
   latencyMode?: string;
   renditions?: Rendition[];
+  // endpoints lists every protocol the stream is served over, most preferred
+  // first. Players try each in order; playbackUrl stays the preferred
+  // HLS-family URL for clients that only take one.
+  endpoints?: PlaybackEndpoint[];
+  // tokenExpiresAt is set when the URLs are signed for a restricted channel;
+  // refetch playback before it passes.
+  tokenExpiresAt?: string;
 };
 
 export type FollowState = {
@@ -185,6 +236,7 @@ export type VodItem = {
   id: string;
   title: string;
   durationSeconds: number;
+  tags?: string[];
   publishedAt: string;
   thumbnailUrl?: string;
   playbackUrl?: string;
@@ -193,6 +245,17 @@ export type VodItem = {
 export type VodCollection = {
   channelId: string;
   items: VodItem[];
+  playbackWithheldReason?: PlaybackWithheldReason;
+};
+
+export type UploadStepStatus = "pending" | "running" | "done" | "failed";
+
+export type UploadStep = {
+  name: string;
+  status: UploadStepStatus;
+  startedAt?: string;
+  finishedAt?: string;
+  detail?: string;
 };
 
 export type UploadItem = {
@@ -208,6 +271,8 @@ export type UploadItem = {
   recordingId?: string;
   playbackUrl?: string;
   error?: string;
+  steps?: UploadStep[];
+  failedStep?: string;
 };
 
 export type CreateUploadPayload = {
@@ -236,9 +301,12 @@ export type ChannelPlaybackResponse = {
   profile: ProfileSummary;
   donationAddresses: CryptoAddress[];
   live: boolean;
+  streamState?: StreamState;
   follow: FollowState;
   subscription?: SubscriptionState;
   playback?: Playback;
+  playbackWithheld?: boolean;
+  playbackWithheldReason?: PlaybackWithheldReason;
   viewerCount?: number;
   chat?: {
     roomId: string;
@@ -357,11 +425,25 @@ export function fetchChannelPlayback(channelId: string): Promise<ChannelPlayback
   return viewerRequest<ChannelPlaybackResponse>(`/api/channels/${channelId}/playback`);
 }
 
-export function searchDirectory(query: string): Promise<DirectoryResponse> {
+// fetchChannelBySlug resolves a channel URL slug. The API answers slugs the
+// channel has since replaced with a 301 to its current slug, which fetch
+// follows, so callers should compare the returned slug with the one they
+// asked for and update the address bar when they differ.
+export function fetchChannelBySlug(slug: string): Promise<ChannelPublic> {
+  return viewerRequest<ChannelPublic>(`/api/channels/by-slug/${encodeURIComponent(slug)}`);
+}
+
+export function searchDirectory(query: string, filters: DirectoryFilters = {}): Promise<DirectoryResponse> {
   const params = new URLSearchParams();
   if (query.trim().length > 0) {
     params.set("q", query.trim());
   }
+  if (filters.language) {
+    params.set("language", filters.language);
+  }
+  if (filters.excludeWarnings && filters.excludeWarnings.length > 0) {
+    params.set("excludeWarnings", filters.excludeWarnings.join(","));
+  }
   const suffix = params.toString();
   return viewerRequest<DirectoryResponse>(`/api/directory${suffix ? `?${suffix}` : ""}`);
 }
@@ -477,6 +559,12 @@ export function fetchManagedChannels(ownerId?: string): Promise<ManagedChannel[]
   return viewerRequest<ManagedChannel[]>(`/api/channels${suffix}`);
 }
 
+export function rotateStreamKey(channelId: string): Promise<ManagedChannel> {
+  return viewerRequest<ManagedChannel>(`/api/channels/${channelId}/stream/rotate`, {
+    method: "POST",
+  });
+}
+
 export function updateChannel(channelId: string, payload: UpdateChannelPayload): Promise<ManagedChannel> {
   return viewerRequest<ManagedChannel>(`/api/channels/${channelId}`, {
     method: "PATCH",